@@ -16,6 +16,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/cron"
 	"github.com/fleetdm/fleet/v4/server/datastore/mysql"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mdm"
@@ -56,7 +57,10 @@ func newVulnerabilitiesSchedule(
 
 	var options []schedule.Option
 
-	options = append(options, schedule.WithLogger(vulnerabilitiesLogger))
+	options = append(options,
+		schedule.WithLogger(vulnerabilitiesLogger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, vulnerabilitiesLogger)),
+	)
 
 	vulnFuncs := getVulnFuncs(ctx, ds, vulnerabilitiesLogger, config)
 	for _, fn := range vulnFuncs {
@@ -101,6 +105,17 @@ func cronVulnerabilities(
 		if err := ds.UpdateVulnerabilityHostCounts(ctx); err != nil {
 			return fmt.Errorf("updating vulnerability host counts: %w", err)
 		}
+
+		level.Info(logger).Log("msg", "updating host vulnerability detections")
+		now := time.Now()
+		if err := ds.UpdateHostCVEDetections(ctx, now); err != nil {
+			return fmt.Errorf("updating host vulnerability detections: %w", err)
+		}
+
+		level.Info(logger).Log("msg", "triggering vulnerability sla webhooks")
+		if err := webhooks.TriggerVulnerabilitySLAWebhook(ctx, ds, kitlog.With(logger, "webhook", "vulnerability_sla"), now); err != nil {
+			return fmt.Errorf("triggering vulnerability sla webhooks: %w", err)
+		}
 	}
 
 	return nil
@@ -429,6 +444,7 @@ func newAutomationsSchedule(
 		// TODO(sarah): Reconfigure settings so automations interval doesn't reside under webhook settings
 		ctx, name, instanceID, appConfig.WebhookSettings.Interval.ValueOr(defaultInterval), ds, ds,
 		schedule.WithLogger(kitlog.With(logger, "cron", name)),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, kitlog.With(logger, "cron", name))),
 		schedule.WithConfigReloadInterval(intervalReload, func(ctx context.Context) (time.Duration, error) {
 			appConfig, err := ds.AppConfig(ctx)
 			if err != nil {
@@ -445,6 +461,14 @@ func newAutomationsSchedule(
 				)
 			},
 		),
+		schedule.WithJob(
+			"certificate_expiry_webhook",
+			func(ctx context.Context) error {
+				return webhooks.TriggerCertificateExpiryWebhook(
+					ctx, ds, kitlog.With(logger, "automation", "certificate_expiry"), time.Now(),
+				)
+			},
+		),
 		schedule.WithJob(
 			"fire_outdated_automations",
 			func(ctx context.Context) error {
@@ -505,7 +529,7 @@ func triggerFailingPoliciesAutomation(
 		switch cfg.AutomationType {
 		case policies.FailingPolicyWebhook:
 			return webhooks.SendFailingPoliciesBatchedPOSTs(
-				ctx, policy, failingPoliciesSet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, time.Now(), logger)
+				ctx, ds, policy, failingPoliciesSet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, time.Now(), logger)
 
 		case policies.FailingPolicyJira:
 			hosts, err := failingPoliciesSet.ListHosts(policy.ID)
@@ -547,6 +571,7 @@ func newWorkerIntegrationsSchedule(
 	logger kitlog.Logger,
 	depStorage *mysql.NanoDEPStorage,
 	commander *apple_mdm.MDMAppleCommander,
+	cronSchedules fleet.CronSchedulesService,
 ) (*schedule.Schedule, error) {
 	const (
 		name = string(fleet.CronWorkerIntegrations)
@@ -599,7 +624,12 @@ func newWorkerIntegrationsSchedule(
 		Log:       logger,
 		Commander: commander,
 	}
-	w.Register(jira, zendesk, macosSetupAsst, appleMDM)
+	hostTransfer := &worker.HostTransfer{
+		Datastore:     ds,
+		Log:           logger,
+		CronSchedules: cronSchedules,
+	}
+	w.Register(jira, zendesk, macosSetupAsst, appleMDM, hostTransfer)
 
 	// Read app config a first time before starting, to clear up any failer client
 	// configuration if we're not on a fleet-owned server. Technically, the ServerURL
@@ -621,6 +651,7 @@ func newWorkerIntegrationsSchedule(
 		ctx, name, instanceID, scheduleInterval, ds, ds,
 		schedule.WithAltLockID("worker"),
 		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
 		schedule.WithJob("integrations_worker", func(ctx context.Context) error {
 			// Read app config to be able to use the latest configuration for integrations.
 			appConfig, err := ds.AppConfig(ctx)
@@ -718,28 +749,9 @@ func newCleanupsAndAggregationSchedule(
 		// Using leader for the lock to be backwards compatilibity with old deployments.
 		schedule.WithAltLockID("leader"),
 		schedule.WithLogger(kitlog.With(logger, "cron", name)),
-		// Run cleanup jobs first.
-		schedule.WithJob(
-			"distributed_query_campaigns",
-			func(ctx context.Context) error {
-				_, err := ds.CleanupDistributedQueryCampaigns(ctx, time.Now().UTC())
-				return err
-			},
-		),
-		schedule.WithJob(
-			"incoming_hosts",
-			func(ctx context.Context) error {
-				_, err := ds.CleanupIncomingHosts(ctx, time.Now())
-				return err
-			},
-		),
-		schedule.WithJob(
-			"carves",
-			func(ctx context.Context) error {
-				_, err := ds.CleanupCarves(ctx, time.Now())
-				return err
-			},
-		),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, kitlog.With(logger, "cron", name))),
+		// Run cleanup jobs first. The cleanups of expired and orphaned rows are
+		// run by the database_maintenance schedule.
 		schedule.WithJob(
 			"expired_hosts",
 			func(ctx context.Context) error {
@@ -747,30 +759,12 @@ func newCleanupsAndAggregationSchedule(
 				return err
 			},
 		),
-		schedule.WithJob(
-			"policy_membership",
-			func(ctx context.Context) error {
-				return ds.CleanupPolicyMembership(ctx, time.Now())
-			},
-		),
 		schedule.WithJob(
 			"sync_enrolled_host_ids",
 			func(ctx context.Context) error {
 				return enrollHostLimiter.SyncEnrolledHostIDs(ctx)
 			},
 		),
-		schedule.WithJob(
-			"cleanup_host_operating_systems",
-			func(ctx context.Context) error {
-				return ds.CleanupHostOperatingSystems(ctx)
-			},
-		),
-		schedule.WithJob(
-			"cleanup_expired_password_reset_requests",
-			func(ctx context.Context) error {
-				return ds.CleanupExpiredPasswordResetRequests(ctx)
-			},
-		),
 		// Run aggregation jobs after cleanups.
 		schedule.WithJob(
 			"query_aggregated_stats",
@@ -784,6 +778,16 @@ func newCleanupsAndAggregationSchedule(
 				return ds.UpdateHostPolicyCounts(ctx)
 			},
 		),
+		schedule.WithJob(
+			"policy_compliance_history",
+			func(ctx context.Context) error {
+				now := time.Now()
+				if err := ds.RecordPolicyComplianceHistory(ctx, now); err != nil {
+					return err
+				}
+				return ds.CleanupPolicyComplianceHistory(ctx, now.Add(-fleet.PolicyComplianceHistoryRetention))
+			},
+		),
 		schedule.WithJob(
 			"aggregated_munki_and_mdm",
 			func(ctx context.Context) error {
@@ -814,40 +818,18 @@ func newCleanupsAndAggregationSchedule(
 				return service.RenewSCEPCertificates(ctx, logger, ds, config, commander)
 			},
 		),
-		schedule.WithJob("query_results_cleanup", func(ctx context.Context) error {
-			config, err := ds.AppConfig(ctx)
-			if err != nil {
-				return err
-			}
-
-			if config.ServerSettings.QueryReportsDisabled {
-				if err = ds.CleanupGlobalDiscardQueryResults(ctx); err != nil {
-					return err
-				}
-			}
-
-			if err = ds.CleanupDiscardedQueryResults(ctx); err != nil {
-				return err
-			}
-
-			return nil
-		}),
-		schedule.WithJob("cleanup_unused_script_contents", func(ctx context.Context) error {
-			return ds.CleanupUnusedScriptContents(ctx)
-		}),
-		schedule.WithJob("cleanup_activities", func(ctx context.Context) error {
-			appConfig, err := ds.AppConfig(ctx)
-			if err != nil {
-				return err
-			}
-			if !appConfig.ActivityExpirySettings.ActivityExpiryEnabled {
-				return nil
-			}
-			// A maxCount of 5,000 means that the cron job will keep the activities (and associated tables)
-			// sizes in control for deployments that generate (5k x 24 hours) ~120,000 activities per day.
-			const maxCount = 5000
-			return ds.CleanupActivitiesAndAssociatedData(ctx, maxCount, appConfig.ActivityExpirySettings.ActivityExpiryWindow)
-		}),
+		schedule.WithJob(
+			"renew_apple_profile_certificates",
+			func(ctx context.Context) error {
+				return service.RenewAppleProfileCertificates(ctx, logger, ds, config, commander)
+			},
+		),
+		schedule.WithJob(
+			"rotate_apple_recovery_lock_passwords",
+			func(ctx context.Context) error {
+				return service.RotateAppleRecoveryLockPasswords(ctx, logger, ds, config, commander)
+			},
+		),
 	)
 
 	return s, nil
@@ -869,6 +851,7 @@ func newFrequentCleanupsSchedule(
 		// Using leader for the lock to be backwards compatilibity with old deployments.
 		schedule.WithAltLockID("leader_frequent_cleanups"),
 		schedule.WithLogger(kitlog.With(logger, "cron", name)),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, kitlog.With(logger, "cron", name))),
 		// Run cleanup jobs first.
 		schedule.WithJob(
 			"redis_live_queries",
@@ -952,6 +935,7 @@ func newUsageStatisticsSchedule(ctx context.Context, instanceID string, ds fleet
 	s := schedule.New(
 		ctx, name, instanceID, defaultInterval, ds, ds,
 		schedule.WithLogger(kitlog.With(logger, "cron", name)),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, kitlog.With(logger, "cron", name))),
 		schedule.WithJob(
 			"try_send_statistics",
 			func(ctx context.Context) error {
@@ -960,6 +944,12 @@ func newUsageStatisticsSchedule(ctx context.Context, instanceID string, ds fleet
 				return trySendStatistics(ctx, ds, fleet.StatisticsFrequency, "https://fleetdm.com/api/v1/webhooks/receive-usage-analytics", config)
 			},
 		),
+		schedule.WithJob(
+			"record_usage_analytics",
+			func(ctx context.Context) error {
+				return recordUsageAnalytics(ctx, ds, time.Now())
+			},
+		),
 	)
 
 	return s, nil
@@ -995,6 +985,22 @@ func trySendStatistics(ctx context.Context, ds fleet.Datastore, frequency time.D
 	return ds.RecordStatisticsSent(ctx)
 }
 
+// recordUsageAnalytics records the host count of the day for the detailed
+// usage analytics if they are enabled, and deletes the ones past their
+// retention.
+func recordUsageAnalytics(ctx context.Context, ds fleet.Datastore, now time.Time) error {
+	ac, err := ds.AppConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if ac.ServerSettings.DetailedUsageAnalytics {
+		if err := ds.RecordUsageAnalyticsHostCount(ctx, now); err != nil {
+			return err
+		}
+	}
+	return ds.CleanupUsageAnalytics(ctx, now.Add(-fleet.UsageAnalyticsRetention))
+}
+
 // newAppleMDMDEPProfileAssigner creates the schedule to run the DEP syncer+assigner.
 // The DEP syncer+assigner fetches devices from Apple Business Manager (aka ABM) and applies
 // the current configured DEP profile to them.
@@ -1012,6 +1018,7 @@ func newAppleMDMDEPProfileAssigner(
 	s := schedule.New(
 		ctx, name, instanceID, periodicity, ds, ds,
 		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
 		schedule.WithJob("dep_syncer", func(ctx context.Context) error {
 			return fleetSyncer.RunAssigner(ctx)
 		}),
@@ -1041,6 +1048,7 @@ func newMDMProfileManager(
 	s := schedule.New(
 		ctx, name, instanceID, defaultInterval, ds, ds,
 		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
 		schedule.WithJob("manage_apple_profiles", func(ctx context.Context) error {
 			return service.ReconcileAppleProfiles(ctx, ds, commander, logger, cfg)
 		}),
@@ -1055,6 +1063,276 @@ func newMDMProfileManager(
 	return s, nil
 }
 
+func newIPhoneIPadRefetcher(
+	ctx context.Context,
+	instanceID string,
+	periodicity time.Duration,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const name = string(fleet.CronAppleMDMIPhoneIPadRefetcher)
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, periodicity, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("refetch_iphones_ipads", func(ctx context.Context) error {
+			return service.RefetchIOSAndIPadOSDevices(ctx, ds, commander, logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newAppleMDMSoftwareRefetcher(
+	ctx context.Context,
+	instanceID string,
+	periodicity time.Duration,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const name = string(fleet.CronAppleMDMSoftwareRefetcher)
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, periodicity, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("refetch_mdm_software", func(ctx context.Context) error {
+			return service.RefetchAppleMDMSoftware(ctx, ds, commander, logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newAppleMDMNotNowRetrier(
+	ctx context.Context,
+	instanceID string,
+	periodicity time.Duration,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const name = string(fleet.CronAppleMDMNotNowRetrier)
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, periodicity, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("retry_not_now_install_application", func(ctx context.Context) error {
+			return service.RetryNotNowInstallApplicationCommands(ctx, ds, commander, logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newMDMProfileRedeliveryCampaignsSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name = string(fleet.CronMDMProfileRedeliveryCampaigns)
+		// the rate of the campaigns is expressed in hosts per minute.
+		defaultInterval = 1 * time.Minute
+	)
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("redeliver_profile_campaigns", func(ctx context.Context) error {
+			return service.RedeliverMDMProfileCampaigns(ctx, ds, logger, time.Now())
+		}),
+	)
+
+	return s, nil
+}
+
+func newMDMProfileRolloutsSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronMDMProfileRollouts)
+		defaultInterval = 5 * time.Minute
+	)
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("process_profile_rollouts", func(ctx context.Context) error {
+			return service.ProcessMDMProfileRollouts(ctx, ds, logger, time.Now())
+		}),
+	)
+
+	return s, nil
+}
+
+func newScriptSchedulesSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name = string(fleet.CronScriptSchedules)
+		// the schedules are expressed in minutes of the hosts' local time, and
+		// are due for fleet.ScriptScheduleRunWindow.
+		defaultInterval = 5 * time.Minute
+	)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("queue_scheduled_scripts", func(ctx context.Context) error {
+			return service.QueueScheduledScripts(ctx, ds, logger, time.Now())
+		}),
+	)
+
+	return s, nil
+}
+
+func newMergeDuplicateHostsSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronMergeDuplicateHosts)
+		defaultInterval = 1 * time.Hour
+	)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("merge_duplicate_hosts", func(ctx context.Context) error {
+			return service.MergeDuplicateHosts(ctx, ds, logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newDataExportsSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronDataExports)
+		defaultInterval = 1 * time.Minute
+	)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("process_data_exports", func(ctx context.Context) error {
+			return service.ProcessDataExports(ctx, ds, logger)
+		}),
+		schedule.WithJob("cleanup_data_exports", func(ctx context.Context) error {
+			n, err := ds.CleanupDataExports(ctx, time.Now().Add(-fleet.DataExportRetention))
+			if err != nil {
+				return err
+			}
+			level.Debug(logger).Log("msg", "deleted expired data exports", "count", n)
+			return nil
+		}),
+	)
+
+	return s, nil
+}
+
+func newAdminNotificationsSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	mdmConfig *config.MDMConfig,
+	mailService fleet.MailService,
+	urlPrefix string,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const name = string(fleet.CronAdminNotifications)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, fleet.AdminNotificationDigestInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("check_conditions", func(ctx context.Context) error {
+			return service.CheckAdminNotificationConditions(ctx, ds, mdmConfig, time.Now())
+		}),
+		schedule.WithJob("email_digests", func(ctx context.Context) error {
+			return service.SendAdminNotificationsDigests(ctx, ds, mailService, urlPrefix, time.Now(), logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newHostWarrantiesSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronHostWarranties)
+		defaultInterval = 1 * time.Hour
+	)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("enrich_host_warranties", func(ctx context.Context) error {
+			return service.EnrichHostWarranties(ctx, ds, logger)
+		}),
+	)
+
+	return s, nil
+}
+
+func newHostHealthScoresSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronHostHealthScores)
+		defaultInterval = 1 * time.Hour
+	)
+
+	logger = kitlog.With(logger, "cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob("update_host_health_scores", func(ctx context.Context) error {
+			return service.UpdateHostHealthScores(ctx, ds, logger)
+		}),
+	)
+
+	return s, nil
+}
+
 func cleanupCronStatsOnShutdown(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, instanceID string) {
 	if err := ds.UpdateAllCronStatsForInstance(ctx, instanceID, fleet.CronStatsStatusPending, fleet.CronStatsStatusCanceled); err != nil {
 		logger.Log("err", "cancel pending cron stats for instance", "details", err)
@@ -1076,6 +1354,7 @@ func newActivitiesStreamingSchedule(
 	s := schedule.New(
 		ctx, name, instanceID, interval, ds, ds,
 		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(cron.NotifyAdminsOnJobError(ds, name, logger)),
 		schedule.WithJob(
 			"cron_activities_streaming",
 			func(ctx context.Context) error {
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"crypto/tls"
+	"crypto/x509"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -51,6 +52,8 @@ import (
 	"github.com/fleetdm/fleet/v4/server/pubsub"
 	"github.com/fleetdm/fleet/v4/server/service"
 	"github.com/fleetdm/fleet/v4/server/service/async"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/latencybudget"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/usageanalytics"
 	"github.com/fleetdm/fleet/v4/server/service/redis_policy_set"
 	"github.com/fleetdm/fleet/v4/server/sso"
 	"github.com/fleetdm/fleet/v4/server/version"
@@ -162,6 +165,14 @@ the way that the Fleet server works.
 				}
 			}
 
+			if config.Server.PrivateKey != "" && len(config.Server.PrivateKey) < 32 {
+				initFatal(errors.New("private key must be at least 32 bytes long"), "validate private key")
+			}
+
+			if config.Server.ClientCertificateEnrollment && !config.Server.TLS {
+				initFatal(errors.New("server.client_certificate_enrollment requires server.tls"), "validate client certificate enrollment")
+			}
+
 			var ds fleet.Datastore
 			var carveStore fleet.CarveStore
 			var installerStore fleet.InstallerStore
@@ -170,10 +181,19 @@ the way that the Fleet server works.
 			if config.MysqlReadReplica.Address != "" {
 				opts = append(opts, mysql.Replica(&config.MysqlReadReplica))
 			}
+			var sqlInterceptor sqlmw.Interceptor
 			if dev && os.Getenv("FLEET_DEV_ENABLE_SQL_INTERCEPTOR") != "" {
-				opts = append(opts, mysql.WithInterceptor(&devSQLInterceptor{
+				sqlInterceptor = &devSQLInterceptor{
 					logger: kitlog.With(logger, "component", "sql-interceptor"),
-				}))
+				}
+			}
+			if config.Server.LatencyBudget != "" {
+				// record the queries of the requests tracked by the latency budget
+				// middleware.
+				sqlInterceptor = latencybudget.NewSQLInterceptor(sqlInterceptor)
+			}
+			if sqlInterceptor != nil {
+				opts = append(opts, mysql.WithInterceptor(sqlInterceptor))
 			}
 
 			if config.Logging.TracingEnabled {
@@ -332,6 +352,7 @@ the way that the Fleet server works.
 
 			resultStore := pubsub.NewRedisQueryResults(redisPool, config.Redis.DuplicateResults,
 				log.With(logger, "component", "query-results"),
+				pubsub.WithResultsBuffer(config.Redis.LiveQueryResultsBufferSize, config.Redis.LiveQueryResultsBufferTTL),
 			)
 			liveQueryStore := live_query.NewRedisLiveQuery(redisPool)
 			ssoSessionStore := sso.NewSessionStore(redisPool)
@@ -544,7 +565,10 @@ the way that the Fleet server works.
 				if os.Getenv("FLEET_DEV_MDM_APPLE_DISABLE_PUSH") == "1" {
 					mdmPushService = nopPusher{}
 				} else {
-					mdmPushService = nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushProviderFactory, nanoMDMLogger)
+					mdmPushService = apple_mdm.NewCoalescingPusher(
+						nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushProviderFactory, nanoMDMLogger),
+						config.MDM.ApplePushCoalesceWindow,
+					)
 				}
 				commander := apple_mdm.NewMDMAppleCommander(mdmStorage, mdmPushService, config.MDM)
 				mdmCheckinAndCommandService = service.NewMDMAppleCheckinAndCommandService(ds, commander, logger)
@@ -707,12 +731,54 @@ the way that the Fleet server works.
 				initFatal(err, "failed to register cleanups_then_aggregations schedule")
 			}
 
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return cron.NewDatabaseMaintenanceSchedule(ctx, instanceID, ds, logger, config)
+			}); err != nil {
+				initFatal(err, "failed to register database_maintenance schedule")
+			}
+
 			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
 				return newUsageStatisticsSchedule(ctx, instanceID, ds, config, license, logger)
 			}); err != nil {
 				initFatal(err, "failed to register stats schedule")
 			}
 
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newScriptSchedulesSchedule(ctx, instanceID, ds, logger)
+			}); err != nil {
+				initFatal(err, "failed to register script_schedules schedule")
+			}
+
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newMergeDuplicateHostsSchedule(ctx, instanceID, ds, logger)
+			}); err != nil {
+				initFatal(err, "failed to register merge_duplicate_hosts schedule")
+			}
+
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newDataExportsSchedule(ctx, instanceID, ds, logger)
+			}); err != nil {
+				initFatal(err, "failed to register data_exports schedule")
+			}
+
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newAdminNotificationsSchedule(ctx, instanceID, ds, &config.MDM, mailService, config.Server.URLPrefix, logger)
+			}); err != nil {
+				initFatal(err, "failed to register admin_notifications schedule")
+			}
+
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newHostWarrantiesSchedule(ctx, instanceID, ds, logger)
+			}); err != nil {
+				initFatal(err, "failed to register host_warranties schedule")
+			}
+
+			if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+				return newHostHealthScoresSchedule(ctx, instanceID, ds, logger)
+			}); err != nil {
+				initFatal(err, "failed to register host_health_scores schedule")
+			}
+
 			vulnerabilityScheduleDisabled := false
 			if config.Vulnerabilities.DisableSchedule {
 				vulnerabilityScheduleDisabled = true
@@ -742,7 +808,7 @@ the way that the Fleet server works.
 				if appCfg.MDM.EnabledAndConfigured {
 					commander = apple_mdm.NewMDMAppleCommander(mdmStorage, mdmPushService, config.MDM)
 				}
-				return newWorkerIntegrationsSchedule(ctx, instanceID, ds, logger, depStorage, commander)
+				return newWorkerIntegrationsSchedule(ctx, instanceID, ds, logger, depStorage, commander, cronSchedules)
 			}); err != nil {
 				initFatal(err, "failed to register worker integrations schedule")
 			}
@@ -769,6 +835,55 @@ the way that the Fleet server works.
 				}); err != nil {
 					initFatal(err, "failed to register mdm_apple_profile_manager schedule")
 				}
+				if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+					return newMDMProfileRedeliveryCampaignsSchedule(ctx, instanceID, ds, logger)
+				}); err != nil {
+					initFatal(err, "failed to register mdm_profile_redelivery_campaigns schedule")
+				}
+				if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+					return newMDMProfileRolloutsSchedule(ctx, instanceID, ds, logger)
+				}); err != nil {
+					initFatal(err, "failed to register mdm_profile_rollouts schedule")
+				}
+			}
+
+			if appCfg.MDM.EnabledAndConfigured {
+				if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+					return newIPhoneIPadRefetcher(
+						ctx,
+						instanceID,
+						10*time.Minute,
+						ds,
+						apple_mdm.NewMDMAppleCommander(mdmStorage, mdmPushService, config.MDM),
+						logger,
+					)
+				}); err != nil {
+					initFatal(err, "failed to register apple_mdm_iphone_ipad_refetcher schedule")
+				}
+				if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+					return newAppleMDMSoftwareRefetcher(
+						ctx,
+						instanceID,
+						10*time.Minute,
+						ds,
+						apple_mdm.NewMDMAppleCommander(mdmStorage, mdmPushService, config.MDM),
+						logger,
+					)
+				}); err != nil {
+					initFatal(err, "failed to register apple_mdm_software_refetcher schedule")
+				}
+				if err := cronSchedules.StartCronSchedule(func() (fleet.CronSchedule, error) {
+					return newAppleMDMNotNowRetrier(
+						ctx,
+						instanceID,
+						5*time.Minute,
+						ds,
+						apple_mdm.NewMDMAppleCommander(mdmStorage, mdmPushService, config.MDM),
+						logger,
+					)
+				}); err != nil {
+					initFatal(err, "failed to register apple_mdm_not_now_retrier schedule")
+				}
 			}
 
 			if license.IsPremium() && config.Activity.EnableAuditLog {
@@ -832,13 +947,57 @@ the way that the Fleet server works.
 				KeyPrefix: "ratelimit::",
 			}
 
+			var latencyBudget *latencybudget.Middleware
+			if config.Server.LatencyBudget != "" {
+				var explainer latencybudget.Explainer
+				if config.Server.LatencyBudgetExplain {
+					explainer = mds
+				}
+				latencyBudget = latencybudget.NewMiddleware(
+					kitlog.With(logger, "component", "latency-budget"), config.Server.LatencyBudgetForPath, explainer,
+				)
+			}
+
+			usageAnalytics := usageanalytics.NewMiddleware()
+			go func() {
+				flushUsageAnalytics := func() {
+					// the requests are counted by every Fleet instance, each instance
+					// adds its counts to the ones stored.
+					var store usageanalytics.Store
+					if ac, err := ds.AppConfig(ctx); err != nil {
+						level.Info(logger).Log("msg", "failed to load app config to flush usage analytics", "err", err)
+						return
+					} else if ac.ServerSettings.DetailedUsageAnalytics {
+						store = ds
+					}
+					if err := usageAnalytics.Flush(ctx, store); err != nil {
+						level.Info(logger).Log("msg", "failed to flush usage analytics", "err", err)
+					}
+				}
+
+				flushUsageAnalyticsTick := time.NewTicker(1 * time.Minute)
+				defer flushUsageAnalyticsTick.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-flushUsageAnalyticsTick.C:
+						flushUsageAnalytics()
+					}
+				}
+			}()
+
 			var apiHandler, frontendHandler http.Handler
 			{
 				frontendHandler = service.PrometheusMetricsHandler(
 					"get_frontend",
 					service.ServeFrontend(config.Server.URLPrefix, config.Server.SandboxEnabled, httpLogger),
 				)
-				apiHandler = service.MakeHandler(svc, config, httpLogger, limiterStore)
+				extra := []service.ExtraHandlerOption{service.WithUsageAnalytics(usageAnalytics)}
+				if latencyBudget != nil {
+					extra = append(extra, service.WithLatencyBudget(latencyBudget))
+				}
+				apiHandler = service.MakeHandler(svc, config, httpLogger, limiterStore, extra...)
 
 				setupRequired, err := svc.SetupRequired(baseCtx)
 				if err != nil {
@@ -935,7 +1094,7 @@ the way that the Fleet server works.
 			rootMux.Handle("/", frontendHandler)
 
 			debugHandler := &debugMux{
-				fleetAuthenticatedHandler: service.MakeDebugHandler(svc, config, logger, eh, ds),
+				fleetAuthenticatedHandler: service.MakeDebugHandler(svc, config, logger, eh, ds, latencyBudget),
 			}
 			rootMux.Handle("/debug/", debugHandler)
 
@@ -1008,6 +1167,16 @@ the way that the Fleet server works.
 				} else {
 					logger.Log("transport", "https", "address", config.Server.Address, "msg", "listening")
 					srv.TLSConfig = getTLSConfig(config.Server.TLSProfile)
+					if config.Server.ClientCertificateEnrollment {
+						clientCAs, err := getClientCAPool(config.Server.ClientCA, appleSCEPCertPEM)
+						if err != nil {
+							initFatal(err, "load client certificate authorities")
+						}
+						// Client certificates are optional: the hosts that don't
+						// present one still enroll with an enroll secret.
+						srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+						srv.TLSConfig.ClientCAs = clientCAs
+					}
 					errs <- srv.ListenAndServeTLS(
 						config.Server.Cert,
 						config.Server.Key,
@@ -1133,6 +1302,29 @@ func getTLSConfig(profile string) *tls.Config {
 	return &cfg
 }
 
+// getClientCAPool returns the pool of certificate authorities trusted to issue
+// the client certificates used for enrollment: the certificates of the
+// caPath PEM file and, if MDM is configured, Fleet's Apple SCEP CA.
+func getClientCAPool(caPath string, scepCertPEM []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if caPath != "" {
+		pemBytes, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no valid certificate found in client CA file %s", caPath)
+		}
+	}
+	if len(scepCertPEM) > 0 && !pool.AppendCertsFromPEM(scepCertPEM) {
+		return nil, errors.New("invalid Apple SCEP CA certificate")
+	}
+	if caPath == "" && len(scepCertPEM) == 0 {
+		return nil, errors.New("server.client_ca is required when Apple MDM is not configured")
+	}
+	return pool, nil
+}
+
 // devSQLInterceptor is a sql interceptor to be used for development purposes.
 type devSQLInterceptor struct {
 	sqlmw.NullInterceptor
@@ -252,6 +252,7 @@ func TestAutomationsSchedule(t *testing.T) {
 	ds.GetLatestCronStatsFunc = mockStatsStore.GetLatestCronStats
 	ds.InsertCronStatsFunc = mockStatsStore.InsertCronStats
 	ds.UpdateCronStatsFunc = mockStatsStore.UpdateCronStats
+	ds.IsCronSchedulePausedFunc = mockStatsStore.IsCronSchedulePaused
 
 	calledOnce := make(chan struct{})
 	calledTwice := make(chan struct{})
@@ -321,6 +322,7 @@ func TestCronVulnerabilitiesCreatesDatabasesPath(t *testing.T) {
 	ds.GetLatestCronStatsFunc = mockStatsStore.GetLatestCronStats
 	ds.InsertCronStatsFunc = mockStatsStore.InsertCronStats
 	ds.UpdateCronStatsFunc = mockStatsStore.UpdateCronStats
+	ds.IsCronSchedulePausedFunc = mockStatsStore.IsCronSchedulePaused
 
 	vulnPath := filepath.Join(t.TempDir(), "something")
 	require.NoDirExists(t, vulnPath)
@@ -598,6 +600,7 @@ func TestCronVulnerabilitiesSkipMkdirIfDisabled(t *testing.T) {
 	ds.GetLatestCronStatsFunc = mockStatsStore.GetLatestCronStats
 	ds.InsertCronStatsFunc = mockStatsStore.InsertCronStats
 	ds.UpdateCronStatsFunc = mockStatsStore.UpdateCronStats
+	ds.IsCronSchedulePausedFunc = mockStatsStore.IsCronSchedulePaused
 
 	vulnPath := filepath.Join(t.TempDir(), "something")
 	require.NoDirExists(t, vulnPath)
@@ -677,6 +680,7 @@ func TestAutomationsScheduleLockDuration(t *testing.T) {
 	ds.GetLatestCronStatsFunc = mockStatsStore.GetLatestCronStats
 	ds.InsertCronStatsFunc = mockStatsStore.InsertCronStats
 	ds.UpdateCronStatsFunc = mockStatsStore.UpdateCronStats
+	ds.IsCronSchedulePausedFunc = mockStatsStore.IsCronSchedulePaused
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -735,6 +739,7 @@ func TestAutomationsScheduleIntervalChange(t *testing.T) {
 	ds.GetLatestCronStatsFunc = mockStatsStore.GetLatestCronStats
 	ds.InsertCronStatsFunc = mockStatsStore.InsertCronStats
 	ds.UpdateCronStatsFunc = mockStatsStore.UpdateCronStats
+	ds.IsCronSchedulePausedFunc = mockStatsStore.IsCronSchedulePaused
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -158,6 +158,9 @@ func TestApplyTeamSpecs(t *testing.T) {
 		teamsByName[team.Name] = team
 		return team, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	enrolledSecretsCalled := make(map[uint][]*fleet.EnrollSecret)
 	ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
@@ -579,6 +582,9 @@ func TestApplyAppConfig(t *testing.T) {
 		savedAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
 		return nil
@@ -720,6 +726,9 @@ func TestApplyAppConfigDryRunIssue(t *testing.T) {
 		currentAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	// first, set the default app config's agent options as set after fleetctl setup
 	name := writeTmpYml(t, `---
@@ -814,6 +823,9 @@ func TestApplyAppConfigUnknownFields(t *testing.T) {
 		savedAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	name := writeTmpYml(t, `---
 apiVersion: v1
@@ -852,6 +864,9 @@ func TestApplyAppConfigDeprecatedFields(t *testing.T) {
 		savedAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	name := writeTmpYml(t, `---
 apiVersion: v1
@@ -1150,6 +1165,9 @@ func TestApplyAsGitOps(t *testing.T) {
 		currentAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	savedTeam := &fleet.Team{ID: 123}
 	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
@@ -1707,6 +1725,9 @@ func TestCanApplyIntervalsInNanoseconds(t *testing.T) {
 		savedAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	name := writeTmpYml(t, `---
 apiVersion: v1
@@ -1742,6 +1763,9 @@ func TestCanApplyIntervalsUsingDurations(t *testing.T) {
 		savedAppConfig = config
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	name := writeTmpYml(t, `---
 apiVersion: v1
@@ -1854,6 +1878,9 @@ func TestApplyMacosSetup(t *testing.T) {
 			mockStore.appConfig = clone.(*fleet.AppConfig)
 			return nil
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 
 		ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 			teamsByName[team.Name] = team
@@ -2537,6 +2564,9 @@ func TestApplySpecs(t *testing.T) {
 			teamsByName[team.Name] = team
 			return team, nil
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 
 		ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
 			return nil
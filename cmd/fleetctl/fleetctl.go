@@ -109,6 +109,7 @@ func createApp(
 		upgradePacksCommand(),
 		runScriptCommand(),
 		gitopsCommand(),
+		reportCommand(),
 	}
 	return app
 }
@@ -2197,6 +2197,9 @@ func TestGetTeamsYAMLAndApply(t *testing.T) {
 	ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 		return team, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
 		return nil
 	}
@@ -12,12 +12,16 @@ import (
 func gitopsCommand() *cli.Command {
 	var (
 		flFilename string
+		flEnv      string
 		flDryRun   bool
 	)
 	return &cli.Command{
 		Name:      "gitops",
 		Usage:     "Synchronize Fleet configuration with provided file. This command is intended to be used in a GitOps workflow.",
 		UsageText: `fleetctl gitops [options]`,
+		Subcommands: []*cli.Command{
+			gitopsScaffoldCommand(),
+		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "f",
@@ -26,6 +30,13 @@ func gitopsCommand() *cli.Command {
 				Destination: &flFilename,
 				Usage:       "The file with the GitOps configuration",
 			},
+			&cli.StringFlag{
+				Name:        "env",
+				EnvVars:     []string{"GITOPS_ENV"},
+				Value:       "",
+				Destination: &flEnv,
+				Usage:       "The environment whose overlay is applied to the file, e.g. 'prod' for default.prod.yml next to default.yml",
+			},
 			&cli.BoolFlag{
 				Name:        "dry-run",
 				EnvVars:     []string{"DRY_RUN"},
@@ -44,18 +55,35 @@ func gitopsCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
+			logf := func(format string, a ...interface{}) {
+				_, _ = fmt.Fprintf(c.App.Writer, format, a...)
+			}
+			var overlay []byte
+			if flEnv != "" {
+				if err := spec.ValidateGitOpsEnv(flEnv); err != nil {
+					return err
+				}
+				overlayPath := spec.GitOpsEnvOverlayPath(flFilename, flEnv)
+				overlay, err = os.ReadFile(overlayPath)
+				switch {
+				case errors.Is(err, os.ErrNotExist):
+					// the file is the same in all environments
+					logf("[+] no overlay for the %s environment, applying %s as is\n", flEnv, flFilename)
+				case err != nil:
+					return err
+				default:
+					logf("[+] applying the %s environment overlay %s\n", flEnv, overlayPath)
+				}
+			}
 			fleetClient, err := clientFromCLI(c)
 			if err != nil {
 				return err
 			}
 			baseDir := filepath.Dir(flFilename)
-			config, err := spec.GitOpsFromBytes(b, baseDir)
+			config, err := spec.GitOpsFromBytesWithOverlay(b, overlay, baseDir)
 			if err != nil {
 				return err
 			}
-			logf := func(format string, a ...interface{}) {
-				_, _ = fmt.Fprintf(c.App.Writer, format, a...)
-			}
 			appConfig, err := fleetClient.GetAppConfig()
 			if err != nil {
 				return err
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/urfave/cli/v2"
+)
+
+var nonSlugCharsRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// gitopsTeamSlug returns the name used for the files of the team in the
+// GitOps repository, e.g. "workstations-canary" for "💻 Workstations (canary)".
+func gitopsTeamSlug(teamName string) string {
+	return strings.Trim(nonSlugCharsRegexp.ReplaceAllString(strings.ToLower(teamName), "-"), "-")
+}
+
+// gitopsScaffoldFile is a file created by the scaffold command. Path is the
+// format of the path of the file relative to the root directory of the GitOps
+// repository, with the team slug as argument.
+type gitopsScaffoldFile struct {
+	Path     string
+	Template *template.Template
+}
+
+var gitopsScaffoldFiles = []gitopsScaffoldFile{
+	{
+		Path: "teams/%s.yml",
+		Template: template.Must(template.New("").Parse(`name: {{ .QuotedName }}
+team_settings:
+  features:
+    enable_host_users: true
+    enable_software_inventory: true
+  host_expiry_settings:
+    host_expiry_enabled: false
+    host_expiry_window: 0
+  secrets:
+    # Set this environment variable to the enroll secret of the team when
+    # running fleetctl gitops.
+    - secret: ${{ .SecretEnvVar }}
+agent_options:
+  path: ../lib/{{ .Slug }}.agent-options.yml
+controls:
+  macos_settings:
+    custom_settings:
+      # Uncomment to deliver the configuration profile to the macOS hosts of
+      # the team once it is edited.
+      # - path: ../lib/configuration-profiles/{{ .Slug }}-example.mobileconfig
+  windows_settings:
+    custom_settings:
+  scripts:
+policies:
+  - path: ../lib/{{ .Slug }}.policies.yml
+queries:
+  - path: ../lib/{{ .Slug }}.queries.yml
+`)),
+	},
+	{
+		Path: "lib/%s.agent-options.yml",
+		Template: template.Must(template.New("").Parse(`config:
+  decorators:
+    load:
+      - SELECT uuid AS host_uuid FROM system_info;
+      - SELECT hostname AS hostname FROM system_info;
+  options:
+    disable_distributed: false
+    distributed_interval: 10
+    distributed_plugin: tls
+    distributed_tls_max_attempts: 3
+    logger_tls_endpoint: /api/osquery/log
+    logger_tls_period: 10
+    pack_delimiter: /
+`)),
+	},
+	{
+		Path: "lib/%s.policies.yml",
+		Template: template.Must(template.New("").Parse(`# Policies of the {{ .Name }} team, for example:
+#
+# - name: macOS - Enable FileVault
+#   query: SELECT 1 FROM filevault_status WHERE status = 'FileVault is On.';
+#   critical: false
+#   description: This policy checks if FileVault (disk encryption) is enabled.
+#   resolution: As an IT admin, turn on disk encryption in Fleet.
+#   platform: darwin
+`)),
+	},
+	{
+		Path: "lib/%s.queries.yml",
+		Template: template.Must(template.New("").Parse(`# Queries of the {{ .Name }} team, for example:
+#
+# - name: Collect USB devices
+#   description: Collects the USB devices that are currently connected to macOS and Linux hosts.
+#   query: SELECT model, vendor FROM usb_devices;
+#   interval: 300
+#   platform: darwin,linux
+#   min_osquery_version: ""
+#   observer_can_run: true
+#   automations_enabled: false
+#   logging: snapshot
+`)),
+	},
+	{
+		Path: "lib/configuration-profiles/%s-example.mobileconfig",
+		Template: template.Must(template.New("").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadContent</key>
+	<array>
+		<!-- Add the payloads of the profile here. -->
+	</array>
+	<key>PayloadDisplayName</key>
+	<string>{{ html .Name }} example</string>
+	<key>PayloadIdentifier</key>
+	<string>com.example.{{ .Slug }}</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+	<key>PayloadUUID</key>
+	<string>REPLACE-WITH-A-UUID</string>
+	<key>PayloadVersion</key>
+	<integer>1</integer>
+</dict>
+</plist>
+`)),
+	},
+}
+
+func gitopsScaffoldCommand() *cli.Command {
+	var (
+		flTeam string
+		flDir  string
+	)
+	return &cli.Command{
+		Name:      "scaffold",
+		Usage:     "Create the GitOps files of a new team with placeholder policies, queries and configuration profile",
+		UsageText: `fleetctl gitops scaffold --team "Workstations" [--dir .]`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "team",
+				Destination: &flTeam,
+				Usage:       "The name of the team",
+				Required:    true,
+			},
+			&cli.StringFlag{
+				Name:        "dir",
+				Value:       ".",
+				Destination: &flDir,
+				Usage:       "The root directory of the GitOps repository",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			slug := gitopsTeamSlug(flTeam)
+			if slug == "" {
+				return errors.New("--team must contain at least one letter or digit")
+			}
+			data := struct {
+				Name         string
+				QuotedName   string
+				Slug         string
+				SecretEnvVar string
+			}{
+				Name: flTeam,
+				// the name is quoted so that YAML special characters are kept as is
+				QuotedName:   strconv.Quote(flTeam),
+				Slug:         slug,
+				SecretEnvVar: strings.ToUpper(strings.ReplaceAll(slug, "-", "_")) + "_ENROLL_SECRET",
+			}
+
+			// render all the files first so that nothing is created if a file
+			// already exists.
+			contents := make(map[string][]byte, len(gitopsScaffoldFiles))
+			paths := make([]string, 0, len(gitopsScaffoldFiles))
+			for _, f := range gitopsScaffoldFiles {
+				p := filepath.Join(flDir, filepath.FromSlash(fmt.Sprintf(f.Path, slug)))
+				var content bytes.Buffer
+				if err := f.Template.Execute(&content, data); err != nil {
+					return fmt.Errorf("rendering %s: %w", p, err)
+				}
+				if _, err := os.Stat(p); err == nil {
+					return fmt.Errorf("%s already exists, the team may already be in the GitOps repository", p)
+				} else if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				contents[p] = content.Bytes()
+				paths = append(paths, p)
+			}
+
+			for _, p := range paths {
+				if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+					return err
+				}
+				if err := os.WriteFile(p, contents[p], defaultFileMode); err != nil {
+					return err
+				}
+				fmt.Fprintf(c.App.Writer, "[+] created %s\n", p)
+			}
+
+			fmt.Fprintf(c.App.Writer, "[!] set the %s environment variable to the enroll secret of the team, then apply the team with:\n", data.SecretEnvVar)
+			fmt.Fprintf(c.App.Writer, "    fleetctl gitops -f %s\n", paths[0])
+			return nil
+		},
+	}
+}
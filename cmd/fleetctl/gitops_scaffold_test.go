@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/pkg/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitOpsTeamSlug(t *testing.T) {
+	cases := map[string]string{
+		"Workstations":            "workstations",
+		"💻 Workstations (canary)": "workstations-canary",
+		"  Servers -- Linux  ":    "servers-linux",
+		"🔥":                       "",
+	}
+	for name, want := range cases {
+		assert.Equal(t, want, gitopsTeamSlug(name), name)
+	}
+}
+
+func TestGitOpsScaffold(t *testing.T) {
+	dir := t.TempDir()
+
+	out := runAppForTest(t, []string{"gitops", "scaffold", "--team", `💻 Workstations: "canary"`, "--dir", dir})
+	require.Contains(t, out, "WORKSTATIONS_CANARY_ENROLL_SECRET")
+
+	teamFile := filepath.Join(dir, "teams", "workstations-canary.yml")
+	require.Contains(t, out, "fleetctl gitops -f "+teamFile)
+	for _, f := range []string{
+		teamFile,
+		filepath.Join(dir, "lib", "workstations-canary.agent-options.yml"),
+		filepath.Join(dir, "lib", "workstations-canary.policies.yml"),
+		filepath.Join(dir, "lib", "workstations-canary.queries.yml"),
+		filepath.Join(dir, "lib", "configuration-profiles", "workstations-canary-example.mobileconfig"),
+	} {
+		require.FileExists(t, f)
+	}
+
+	// the generated team file is a valid GitOps file
+	t.Setenv("WORKSTATIONS_CANARY_ENROLL_SECRET", "secret")
+	b, err := os.ReadFile(teamFile)
+	require.NoError(t, err)
+	gitops, err := spec.GitOpsFromBytes(b, filepath.Dir(teamFile))
+	require.NoError(t, err)
+	require.NotNil(t, gitops.TeamName)
+	assert.Equal(t, `💻 Workstations: "canary"`, *gitops.TeamName)
+	assert.Empty(t, gitops.Policies)
+	assert.Empty(t, gitops.Queries)
+
+	// nothing is overwritten
+	_, err = runAppNoChecks([]string{"gitops", "scaffold", "--team", "Workstations canary", "--dir", dir})
+	require.ErrorContains(t, err, "already exists")
+
+	runAppCheckErr(t, []string{"gitops", "scaffold", "--team", "🔥", "--dir", dir}, "--team must contain at least one letter or digit")
+}
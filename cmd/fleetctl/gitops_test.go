@@ -30,6 +30,9 @@ func TestBasicGlobalGitOps(t *testing.T) {
 	) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.BulkSetPendingMDMHostProfilesFunc = func(
 		ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string,
 	) error {
@@ -123,6 +126,173 @@ org_settings:
 	assert.Empty(t, enrolledSecrets)
 }
 
+func TestGitOpsSecretVariables(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	ds.BatchSetMDMProfilesFunc = func(
+		ctx context.Context, tmID *uint, macProfiles []*fleet.MDMAppleConfigProfile, winProfiles []*fleet.MDMWindowsConfigProfile,
+		macDecls []*fleet.MDMAppleDeclaration,
+	) error {
+		return nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(
+		ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string,
+	) error {
+		return nil
+	}
+	var savedScripts []*fleet.Script
+	ds.BatchSetScriptsFunc = func(ctx context.Context, tmID *uint, scripts []*fleet.Script) error {
+		savedScripts = scripts
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.ListGlobalPoliciesFunc = func(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) { return nil, nil }
+	ds.ListQueriesFunc = func(ctx context.Context, opts fleet.ListQueryOptions) ([]*fleet.Query, error) { return nil, nil }
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, config *fleet.AppConfig) error {
+		return nil
+	}
+	ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
+		return nil
+	}
+	stored := make(map[string]string)
+	ds.UpsertSecretVariablesFunc = func(ctx context.Context, secrets []fleet.SecretVariablePayload) error {
+		for _, secret := range secrets {
+			stored[secret.Name] = secret.Value
+		}
+		return nil
+	}
+	ds.GetSecretVariablesFunc = func(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
+		var secrets []*fleet.SecretVariable
+		for _, name := range names {
+			if v, ok := stored[name]; ok {
+				secrets = append(secrets, &fleet.SecretVariable{Name: name, Value: v})
+			}
+		}
+		return secrets, nil
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/script.sh", []byte(`curl -H "Authorization: $FLEET_SECRET_API_TOKEN" https://example.com`), 0o644))
+	require.NoError(t, os.WriteFile(dir+"/gitops.yml", []byte(`
+controls:
+  scripts:
+    - path: ./script.sh
+queries:
+policies:
+agent_options:
+org_settings:
+  server_settings:
+    server_url: https://fleet.example.com
+  org_info:
+    org_name: GitOps Test
+  secrets:
+`), 0o644))
+
+	// the environment variable is required
+	_, err := runAppNoChecks([]string{"gitops", "-f", dir + "/gitops.yml"})
+	require.ErrorContains(t, err, `environment variable "FLEET_SECRET_API_TOKEN" is not set`)
+	require.False(t, ds.UpsertSecretVariablesFuncInvoked)
+
+	t.Setenv("FLEET_SECRET_API_TOKEN", "abc123")
+
+	// dry run does not save the secret variables
+	_ = runAppForTest(t, []string{"gitops", "-f", dir + "/gitops.yml", "--dry-run"})
+	require.Empty(t, stored)
+
+	_ = runAppForTest(t, []string{"gitops", "-f", dir + "/gitops.yml"})
+	require.Equal(t, map[string]string{"API_TOKEN": "abc123"}, stored)
+	require.Len(t, savedScripts, 1)
+	// the script is saved with the reference, not the value
+	require.Contains(t, savedScripts[0].ScriptContents, "$FLEET_SECRET_API_TOKEN")
+}
+
+func TestGitOpsEnvOverlay(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	ds.BatchSetMDMProfilesFunc = func(
+		ctx context.Context, tmID *uint, macProfiles []*fleet.MDMAppleConfigProfile, winProfiles []*fleet.MDMWindowsConfigProfile,
+		macDecls []*fleet.MDMAppleDeclaration,
+	) error {
+		return nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(
+		ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string,
+	) error {
+		return nil
+	}
+	ds.BatchSetScriptsFunc = func(ctx context.Context, tmID *uint, scripts []*fleet.Script) error { return nil }
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.ListGlobalPoliciesFunc = func(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) { return nil, nil }
+	ds.ListQueriesFunc = func(ctx context.Context, opts fleet.ListQueryOptions) ([]*fleet.Query, error) { return nil, nil }
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	savedAppConfig := &fleet.AppConfig{}
+	ds.SaveAppConfigFunc = func(ctx context.Context, config *fleet.AppConfig) error {
+		savedAppConfig = config
+		return nil
+	}
+	var enrolledSecrets []*fleet.EnrollSecret
+	ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
+		enrolledSecrets = secrets
+		return nil
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/default.yml", []byte(`
+controls:
+queries:
+policies:
+agent_options:
+org_settings:
+  server_settings:
+    server_url: https://fleet-staging.example.com
+  org_info:
+    org_name: GitOps Test
+  secrets:
+    - secret: staging-secret
+`), 0o644))
+	require.NoError(t, os.WriteFile(dir+"/default.prod.yml", []byte(`
+org_settings:
+  server_settings:
+    server_url: https://fleet.example.com
+  secrets:
+    - secret: $PROD_ENROLL_SECRET
+`), 0o644))
+	t.Setenv("PROD_ENROLL_SECRET", "prod-secret")
+
+	_, err := runAppNoChecks([]string{"gitops", "-f", dir + "/default.yml", "--env", "../prod"})
+	require.ErrorContains(t, err, "invalid environment")
+
+	// no overlay for staging, the file is applied as is
+	out := runAppForTest(t, []string{"gitops", "-f", dir + "/default.yml", "--env", "staging"})
+	require.Contains(t, out, "no overlay for the staging environment")
+	require.Equal(t, "https://fleet-staging.example.com", savedAppConfig.ServerSettings.ServerURL)
+	require.Equal(t, "GitOps Test", savedAppConfig.OrgInfo.OrgName)
+	require.Len(t, enrolledSecrets, 1)
+	require.Equal(t, "staging-secret", enrolledSecrets[0].Secret)
+
+	out = runAppForTest(t, []string{"gitops", "-f", dir + "/default.yml", "--env", "prod"})
+	require.Contains(t, out, "applying the prod environment overlay")
+	require.Equal(t, "https://fleet.example.com", savedAppConfig.ServerSettings.ServerURL)
+	require.Equal(t, "GitOps Test", savedAppConfig.OrgInfo.OrgName)
+	require.Len(t, enrolledSecrets, 1)
+	require.Equal(t, "prod-secret", enrolledSecrets[0].Secret)
+}
+
 func TestBasicTeamGitOps(t *testing.T) {
 	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
 	_, ds := runServerWithMockedDS(
@@ -139,6 +309,9 @@ func TestBasicTeamGitOps(t *testing.T) {
 	) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.BulkSetPendingMDMHostProfilesFunc = func(
 		ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string,
 	) error {
@@ -265,6 +438,9 @@ func TestFullGlobalGitOps(t *testing.T) {
 		appliedWinProfiles = winProfiles
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
 		return nil
 	}
@@ -429,6 +605,9 @@ func TestFullTeamGitOps(t *testing.T) {
 		appliedWinProfiles = winProfiles
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
 		return nil
 	}
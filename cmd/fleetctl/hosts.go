@@ -2,7 +2,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/urfave/cli/v2"
 )
 
@@ -11,6 +14,9 @@ const (
 	labelFlagName       = "label"
 	statusFlagName      = "status"
 	searchQueryFlagName = "search_query"
+	hostFlagName        = "host"
+	notesFlagName       = "notes"
+	customFieldFlagName = "custom-field"
 )
 
 func hostsCommand() *cli.Command {
@@ -19,6 +25,7 @@ func hostsCommand() *cli.Command {
 		Usage: "Manage Fleet hosts",
 		Subcommands: []*cli.Command{
 			transferCommand(),
+			editHostCommand(),
 		},
 	}
 }
@@ -81,3 +88,65 @@ func transferCommand() *cli.Command {
 		},
 	}
 }
+
+func editHostCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "edit",
+		Usage:     "Edit the notes and custom fields of a host",
+		UsageText: `fleetctl hosts edit --host=<host> [--notes=<notes>] [--custom-field=<name>=<value>...]`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     hostFlagName,
+				Usage:    "A host, specified by hostname, serial number, UUID, osquery host ID, or node key.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  notesFlagName,
+				Usage: "The notes of the host. Use '' to clear the notes",
+			},
+			&cli.StringSliceFlag{
+				Name:  customFieldFlagName,
+				Usage: "A custom field to set, as <name>=<value>. Use <name>= to clear the field. Can be repeated",
+			},
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			var payload fleet.HostCustomFieldsPayload
+			if c.IsSet(notesFlagName) {
+				notes := c.String(notesFlagName)
+				payload.Notes = &notes
+			}
+			for _, field := range c.StringSlice(customFieldFlagName) {
+				name, value, ok := strings.Cut(field, "=")
+				if !ok || name == "" {
+					return fmt.Errorf("invalid --%s %q, must be <name>=<value>", customFieldFlagName, field)
+				}
+				if payload.CustomFields == nil {
+					payload.CustomFields = make(map[string]*string)
+				}
+				if value == "" {
+					payload.CustomFields[name] = nil
+				} else {
+					payload.CustomFields[name] = &value
+				}
+			}
+			if payload.Notes == nil && len(payload.CustomFields) == 0 {
+				return fmt.Errorf("You need to define either --%s or --%s", notesFlagName, customFieldFlagName)
+			}
+
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			ident := c.String(hostFlagName)
+			if _, err := client.UpdateHostCustomFields(ident, payload); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.App.Writer, "[+] updated host %q\n", ident)
+			return nil
+		},
+	}
+}
@@ -50,6 +50,10 @@ func TestHostsTransferByHosts(t *testing.T) {
 		return nil, nil
 	}
 
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
+
 	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: tid, Name: "team1"}, nil
 	}
@@ -119,6 +123,10 @@ func TestHostsTransferByLabel(t *testing.T) {
 		return nil, nil
 	}
 
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
+
 	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: tid, Name: "team1"}, nil
 	}
@@ -187,6 +195,10 @@ func TestHostsTransferByStatus(t *testing.T) {
 		return nil, nil
 	}
 
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
+
 	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: tid, Name: "team1"}, nil
 	}
@@ -244,6 +256,10 @@ func TestHostsTransferByStatusAndSearchQuery(t *testing.T) {
 		return nil, nil
 	}
 
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
+
 	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: tid, Name: "team1"}, nil
 	}
@@ -261,3 +277,87 @@ func TestHostsTransferByStatusAndSearchQuery(t *testing.T) {
 		[]string{"hosts", "transfer", "--team", "team1", "--status", "online", "--search_query", "somequery"}))
 	require.True(t, ds.NewActivityFuncInvoked)
 }
+
+func TestHostsEdit(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostCustomFields: []fleet.HostCustomFieldDefinition{
+			{Name: "owner", Type: fleet.HostCustomFieldTypeString},
+			{Name: "asset_tag", Type: fleet.HostCustomFieldTypeString},
+		}}, nil
+	}
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		require.Equal(t, "host1", identifier)
+		return &fleet.Host{ID: 42, Hostname: "host1"}, nil
+	}
+	ds.LoadHostSoftwareFunc = func(ctx context.Context, host *fleet.Host, includeCVEScores bool) error {
+		return nil
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return nil, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return nil, nil
+	}
+	ds.ListHostBatteriesFunc = func(ctx context.Context, hid uint) ([]*fleet.HostBattery, error) {
+		return nil, nil
+	}
+	ds.ListPoliciesForHostFunc = func(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error) {
+		return nil, nil
+	}
+	ds.ListHostDeviceMappingFunc = func(ctx context.Context, id uint) ([]*fleet.HostDeviceMapping, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, &notFoundError{}
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, &notFoundError{}
+	}
+	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
+		return &fleet.HostLockWipeStatus{}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		require.Equal(t, uint(42), id)
+		return &fleet.Host{ID: 42, Hostname: "host1"}, nil
+	}
+
+	var gotPayload fleet.HostCustomFieldsPayload
+	ds.UpdateHostCustomFieldsFunc = func(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
+		require.Equal(t, uint(42), hostID)
+		gotPayload = payload
+		return nil
+	}
+	ds.GetHostCustomFieldsFunc = func(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
+		return &fleet.HostCustomFields{HostID: hostID}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		act, ok := activity.(fleet.ActivityTypeEditedHostCustomFields)
+		require.True(t, ok)
+		require.Equal(t, uint(42), act.HostID)
+		return nil
+	}
+
+	runAppCheckErr(t, []string{"hosts", "edit", "--host", "host1"}, "You need to define either --notes or --custom-field")
+	runAppCheckErr(t, []string{"hosts", "edit", "--host", "host1", "--custom-field", "owner"}, `invalid --custom-field "owner", must be <name>=<value>`)
+
+	assert.Equal(t, "[+] updated host \"host1\"\n", runAppForTest(t, []string{
+		"hosts", "edit", "--host", "host1", "--notes", "loaner", "--custom-field", "owner=alice", "--custom-field", "asset_tag=",
+	}))
+	require.True(t, ds.UpdateHostCustomFieldsFuncInvoked)
+	require.True(t, ds.NewActivityFuncInvoked)
+	require.NotNil(t, gotPayload.Notes)
+	require.Equal(t, "loaner", *gotPayload.Notes)
+	require.Len(t, gotPayload.CustomFields, 2)
+	require.Equal(t, "alice", *gotPayload.CustomFields["owner"])
+	require.Nil(t, gotPayload.CustomFields["asset_tag"])
+
+	ds.UpdateHostCustomFieldsFuncInvoked = false
+	runAppCheckErr(t, []string{"hosts", "edit", "--host", "host1", "--custom-field", "nope=1"},
+		`PATCH /api/latest/fleet/hosts/42/custom_fields received status 422 Validation Failed: custom field "nope" is not defined`)
+	require.False(t, ds.UpdateHostCustomFieldsFuncInvoked)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm/backup"
 	"github.com/fleetdm/fleet/v4/server/service"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/urfave/cli/v2"
@@ -28,6 +30,7 @@ func mdmCommand() *cli.Command {
 			mdmLockCommand(),
 			mdmUnlockCommand(),
 			mdmWipeCommand(),
+			mdmAssetsCommand(),
 		},
 	}
 }
@@ -155,6 +158,15 @@ func mdmRunCommand() *cli.Command {
 				return err
 			}
 
+			if len(result.SkippedUUIDs) > 0 {
+				fmt.Fprintf(c.App.Writer, "\nThe command wasn't sent to %d host(s) because they have MDM turned off: %s\n",
+					len(result.SkippedUUIDs), strings.Join(result.SkippedUUIDs, ", "))
+			}
+			if len(result.FailedUUIDs) > 0 {
+				fmt.Fprintf(c.App.Writer, "\nFleet couldn't enqueue the command or notify %d host(s): %s\n",
+					len(result.FailedUUIDs), strings.Join(result.FailedUUIDs, ", "))
+			}
+
 			fmt.Fprintf(c.App.Writer, `
 Hosts will run the command the next time they check into Fleet.
 
@@ -170,24 +182,32 @@ fleetctl get mdm-command-results --id=%v
 
 func mdmLockCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "lock",
-		Usage: "Lock a host when it needs to be returned to your organization.",
+		Name:      "lock",
+		Usage:     "Lock a host when it needs to be returned to your organization.",
+		ArgsUsage: "[<host>]",
 		Flags: []cli.Flag{contextFlag(), debugFlag(), &cli.StringFlag{
-			Name:     "host",
-			Usage:    "The host, specified by identifier, that you want to lock.",
-			Required: true,
+			Name:  "host",
+			Usage: "The host, specified by identifier, that you want to lock. Can also be provided as argument.",
+		}, &cli.StringFlag{
+			Name:  "confirmation",
+			Usage: "The host's serial number, or its confirmation token, to confirm that you want to lock this host.",
 		}},
 		Action: func(c *cli.Context) error {
-			hostIdent := c.String("host")
+			hostIdent := hostIdentFromCLI(c)
 
 			client, host, err := hostMdmActionSetup(c, hostIdent, "lock")
 			if err != nil {
 				return err
 			}
 
-			if err := client.MDMLockHost(host.ID); err != nil {
+			pendingApproval, err := client.MDMLockHost(host.ID, c.String("confirmation"))
+			if err != nil {
 				return fmt.Errorf("Failed to lock host: %w", err)
 			}
+			if pendingApproval {
+				printLockWipePendingApproval(c, "lock", hostIdent)
+				return nil
+			}
 
 			fmt.Fprintf(c.App.Writer, `
 The host will lock when it comes online.
@@ -202,6 +222,14 @@ fleetctl mdm unlock --host=%s
 
 `, hostIdent, hostIdent)
 
+			if host.Platform == "darwin" {
+				fmt.Fprintf(c.App.Writer, `To get the PIN to unlock the host without requesting the unlock (e.g. if the host is offline), copy and run this command:
+
+fleetctl mdm unlock --host=%s --pin-only
+
+`, hostIdent)
+			}
+
 			return nil
 		},
 	}
@@ -209,21 +237,42 @@ fleetctl mdm unlock --host=%s
 
 func mdmUnlockCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "unlock",
-		Usage: "Unlock a host when it needs to be returned to your organization.",
+		Name:      "unlock",
+		Usage:     "Unlock a host when it needs to be returned to your organization.",
+		ArgsUsage: "[<host>]",
 		Flags: []cli.Flag{contextFlag(), debugFlag(), &cli.StringFlag{
-			Name:     "host",
-			Usage:    "The host, specified by identifier, that you want to unlock.",
-			Required: true,
+			Name:  "host",
+			Usage: "The host, specified by identifier, that you want to unlock. Can also be provided as argument.",
+		}, &cli.BoolFlag{
+			Name:  "pin-only",
+			Usage: "Only retrieve the PIN to unlock a locked macOS host, without requesting the host to be unlocked.",
 		}},
 		Action: func(c *cli.Context) error {
-			hostIdent := c.String("host")
+			hostIdent := hostIdentFromCLI(c)
 
 			client, host, err := hostMdmActionSetup(c, hostIdent, "unlock")
 			if err != nil {
 				return err
 			}
 
+			if c.Bool("pin-only") {
+				if host.Platform != "darwin" {
+					return errors.New("The --pin-only flag is only supported for macOS hosts.")
+				}
+				pin, err := client.MDMGetHostUnlockPIN(host.ID)
+				if err != nil {
+					return fmt.Errorf("Failed to get unlock PIN: %w", err)
+				}
+				fmt.Fprintf(c.App.Writer, `
+Use this 6 digit PIN to unlock the host:
+
+%s
+
+`, pin)
+
+				return nil
+			}
+
 			pin, err := client.MDMUnlockHost(host.ID)
 			if err != nil {
 				return fmt.Errorf("Failed to unlock host: %w", err)
@@ -257,15 +306,18 @@ fleetctl get host %s
 // create a mdm command to wipe the device
 func mdmWipeCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "wipe",
-		Usage: "Wipe a host to erase all content on a workstation.",
+		Name:      "wipe",
+		Usage:     "Wipe a host to erase all content on a workstation.",
+		ArgsUsage: "[<host>]",
 		Flags: []cli.Flag{contextFlag(), debugFlag(), &cli.StringFlag{
-			Name:     "host",
-			Usage:    "The host, specified by identifier, that you want to wipe.",
-			Required: true,
+			Name:  "host",
+			Usage: "The host, specified by identifier, that you want to wipe. Can also be provided as argument.",
+		}, &cli.StringFlag{
+			Name:  "confirmation",
+			Usage: "The host's serial number, or its confirmation token, to confirm that you want to wipe this host.",
 		}},
 		Action: func(c *cli.Context) error {
-			hostIdent := c.String("host")
+			hostIdent := hostIdentFromCLI(c)
 
 			client, host, err := hostMdmActionSetup(c, hostIdent, "wipe")
 			if err != nil {
@@ -282,9 +334,14 @@ func mdmWipeCommand() *cli.Command {
 				return errors.New("Can't wipe host because running scripts is disabled in organization settings.")
 			}
 
-			if err := client.MDMWipeHost(host.ID); err != nil {
+			pendingApproval, err := client.MDMWipeHost(host.ID, c.String("confirmation"))
+			if err != nil {
 				return fmt.Errorf("Failed to wipe host: %w", err)
 			}
+			if pendingApproval {
+				printLockWipePendingApproval(c, "wipe", hostIdent)
+				return nil
+			}
 
 			fmt.Fprintf(c.App.Writer, `
 The host will wipe when it comes online.
@@ -298,6 +355,28 @@ fleetctl get host %s`, hostIdent)
 	}
 }
 
+// printLockWipePendingApproval prints the instructions to approve a lock or
+// wipe request that requires a second approver.
+func printLockWipePendingApproval(c *cli.Context, action, hostIdent string) {
+	fmt.Fprintf(c.App.Writer, `
+The %[1]s request must be approved by another user.
+
+To approve, another user must copy and run this command:
+
+fleetctl mdm %[1]s --host=%[2]s --confirmation=<serial number or confirmation token>
+
+`, action, hostIdent)
+}
+
+// hostIdentFromCLI returns the host identifier provided via the --host flag,
+// or as first argument if the flag is not set.
+func hostIdentFromCLI(c *cli.Context) string {
+	if hostIdent := c.String("host"); hostIdent != "" {
+		return hostIdent
+	}
+	return c.Args().First()
+}
+
 // Does some common setup for the host mdm actions such as validating the host,
 // creating the client, getting the desired host, checking permissions, and
 // ensuring MDM is turned on for the host.
@@ -338,3 +417,168 @@ func hostMdmActionSetup(c *cli.Context, hostIdent string, actionType string) (cl
 
 	return client, host, nil
 }
+
+func mdmAssetsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "assets",
+		Usage: "Back up and restore the MDM certificates, keys, tokens and bootstrap packages",
+		Subcommands: []*cli.Command{
+			mdmAssetsExportCommand(),
+			mdmAssetsImportCommand(),
+		},
+	}
+}
+
+func mdmAssetsPassphraseFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "passphrase",
+		EnvVars:  []string{"FLEET_MDM_ASSETS_PASSPHRASE"},
+		Usage:    fmt.Sprintf("The passphrase used to encrypt the bundle (at least %d characters)", backup.MinPassphraseLength),
+		Required: true,
+	}
+}
+
+func mdmAssetsExportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export the MDM assets of the Fleet server as an encrypted bundle.",
+		Flags: []cli.Flag{
+			contextFlag(),
+			debugFlag(),
+			mdmAssetsPassphraseFlag(),
+			&cli.StringFlag{
+				Name:     "output",
+				Usage:    "The path of the bundle file to write.",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return fmt.Errorf("create client: %w", err)
+			}
+
+			bundle, err := client.ExportMDMAssets(c.String("passphrase"))
+			if err != nil {
+				return fmt.Errorf("export MDM assets: %w", err)
+			}
+			if err := os.WriteFile(c.String("output"), bundle, 0o600); err != nil {
+				return fmt.Errorf("write bundle: %w", err)
+			}
+
+			fmt.Fprintf(c.App.Writer, "MDM assets exported to %s. Keep the bundle and its passphrase in a safe place.\n", c.String("output"))
+			return nil
+		},
+	}
+}
+
+// mdmAssetsConfigFiles maps the names of the assets set in the server
+// configuration to the file they are extracted to and the configuration
+// setting to use to load them.
+var mdmAssetsConfigFiles = map[fleet.TenantMDMAssetName]struct{ file, setting string }{
+	fleet.TenantMDMAssetAPNSCert: {"apns_cert.pem", "mdm.apple_apns_cert"},
+	fleet.TenantMDMAssetAPNSKey:  {"apns_key.pem", "mdm.apple_apns_key"},
+	fleet.TenantMDMAssetSCEPCert: {"scep_cert.pem", "mdm.apple_scep_cert"},
+	fleet.TenantMDMAssetSCEPKey:  {"scep_key.pem", "mdm.apple_scep_key"},
+	fleet.TenantMDMAssetABMCert:  {"abm_cert.pem", "mdm.apple_bm_cert"},
+	fleet.TenantMDMAssetABMKey:   {"abm_key.pem", "mdm.apple_bm_key"},
+	fleet.TenantMDMAssetABMToken: {"abm_token.p7m", "mdm.apple_bm_server_token"},
+}
+
+func mdmAssetsImportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Import a bundle of MDM assets exported by a Fleet server.",
+		Flags: []cli.Flag{
+			contextFlag(),
+			debugFlag(),
+			mdmAssetsPassphraseFlag(),
+			&cli.StringFlag{
+				Name:     "bundle",
+				Usage:    "The path of the bundle file to import.",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "overwrite",
+				Usage: "Replace the tenants' MDM assets and the bootstrap packages that already exist.",
+			},
+			&cli.StringFlag{
+				Name:  "extract-dir",
+				Usage: "A directory to extract the assets set in the server configuration (APNs, SCEP and ABM) to, as they can't be imported.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			bundle, err := os.ReadFile(c.String("bundle"))
+			if err != nil {
+				return fmt.Errorf("read bundle: %w", err)
+			}
+
+			if dir := c.String("extract-dir"); dir != "" {
+				if err := extractMDMAssetsConfigFiles(c, bundle, dir); err != nil {
+					return err
+				}
+			}
+
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return fmt.Errorf("create client: %w", err)
+			}
+			result, err := client.ImportMDMAssets(bundle, c.String("passphrase"), c.Bool("overwrite"))
+			if err != nil {
+				return fmt.Errorf("import MDM assets: %w", err)
+			}
+
+			describe := func(item fleet.MDMAssetsImportItem) string {
+				desc := fmt.Sprintf("%s %s", strings.ReplaceAll(string(item.Kind), "_", " "), item.Name)
+				switch {
+				case item.Tenant != "":
+					desc += fmt.Sprintf(" (tenant %s)", item.Tenant)
+				case item.Kind == fleet.MDMBundleItemBootstrapPackage && item.Team == "":
+					desc += " (no team)"
+				case item.Team != "":
+					desc += fmt.Sprintf(" (team %s)", item.Team)
+				}
+				return desc
+			}
+			for _, item := range result.Restored {
+				fmt.Fprintf(c.App.Writer, "[+] restored %s\n", describe(item))
+			}
+			for _, item := range result.Skipped {
+				fmt.Fprintf(c.App.Writer, "[!] skipped %s: %s\n", describe(item), item.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+// extractMDMAssetsConfigFiles decrypts the bundle and writes the assets set
+// in the server configuration to files in dir.
+func extractMDMAssetsConfigFiles(c *cli.Context, data []byte, dir string) error {
+	bundle, err := backup.Open(data, c.String("passphrase"))
+	if err != nil {
+		return fmt.Errorf("open bundle: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create extract directory: %w", err)
+	}
+
+	var settings []string
+	for _, asset := range bundle.Assets {
+		cf, ok := mdmAssetsConfigFiles[asset.Name]
+		if asset.Tenant != "" || !ok {
+			continue
+		}
+		path := filepath.Join(dir, cf.file)
+		if err := os.WriteFile(path, asset.Value, 0o600); err != nil {
+			return fmt.Errorf("write %s: %w", asset.Name, err)
+		}
+		settings = append(settings, fmt.Sprintf("  %s: %s", cf.setting, path))
+	}
+	if len(settings) == 0 {
+		fmt.Fprintln(c.App.Writer, "The bundle has no assets set in the server configuration.")
+		return nil
+	}
+	fmt.Fprintf(c.App.Writer, "Extracted the assets set in the server configuration to %s. Set the following in the Fleet server configuration to use them:\n%s\n\n",
+		dir, strings.Join(settings, "\n"))
+	return nil
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	apple_mdm "github.com/fleetdm/fleet/v4/server/mdm/apple"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanodep/tokenpki"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push"
 	"github.com/fleetdm/fleet/v4/server/mock"
@@ -199,6 +203,12 @@ func TestMDMRunCommand(t *testing.T) {
 			ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
 				return nil, nil
 			}
+			ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+				return nil, &notFoundError{}
+			}
+			ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+				return nil, &notFoundError{}
+			}
 			ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 				return &fleet.HostLockWipeStatus{}, nil
 			}
@@ -224,6 +234,9 @@ func TestMDMRunCommand(t *testing.T) {
 			ds.GetMDMWindowsBitLockerStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostMDMDiskEncryption, error) {
 				return &fleet.HostMDMDiskEncryption{}, nil
 			}
+			ds.ListNanoMDMUserEnrollmentDeviceIDsFunc = func(ctx context.Context, deviceIDs []string) ([]string, error) {
+				return nil, nil
+			}
 			ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
 				h, ok := hostByID[hostID]
 				require.True(t, ok)
@@ -233,6 +246,9 @@ func TestMDMRunCommand(t *testing.T) {
 				return h.MDMInfo, nil
 			}
 
+			enqueuer.EnabledEnrollmentIDsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+				return ids, nil
+			}
 			enqueuer.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
 				return map[string]error{}, nil
 			}
@@ -479,6 +495,9 @@ func TestMDMLockCommand(t *testing.T) {
 		return nil
 	}
 
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, hostUUID string) (*fleet.NanoEnrollment, error) {
+		return nil, nil
+	}
 	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
 		h, ok := hostsByID[hostID]
 		if !ok || h.MDMInfo == nil {
@@ -526,29 +545,92 @@ fleetctl mdm unlock --host=%s
 		flags   []string
 		wantErr string
 	}{
-		{appCfgAllMDM, "no flags", nil, `Required flag "host" not set`},
+		{appCfgAllMDM, "no flags", nil, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "host flag empty", []string{"--host", ""}, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "lock non-existent host", []string{"--host", "notfound"}, `The host doesn't exist. Please provide a valid host identifier.`},
-		{appCfgMacMDM, "valid windows but only macos mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
-		{appCfgWinMDM, "valid macos but only windows mdm", []string{"--host", macEnrolled.UUID}, `macOS MDM isn't turned on.`},
-		{appCfgAllMDM, "valid windows", []string{"--host", winEnrolled.UUID}, ""},
-		{appCfgAllMDM, "valid macos", []string{"--host", macEnrolled.UUID}, ""},
-		{appCfgNoMDM, "valid linux", []string{"--host", linuxEnrolled.UUID}, ""},
-		{appCfgNoMDM, "valid windows but no mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
-		{appCfgNoMDM, "valid macos but no mdm", []string{"--host", macEnrolled.UUID}, `macOS MDM isn't turned on.`},
-		{appCfgMacMDM, "valid macos but not enrolled", []string{"--host", macNotEnrolled.UUID}, `Can't lock the host because it doesn't have MDM turned on.`},
-		{appCfgWinMDM, "valid windows but not enrolled", []string{"--host", winNotEnrolled.UUID}, `Can't lock the host because it doesn't have MDM turned on.`},
-		{appCfgWinMDM, "valid windows but pending ", []string{"--host", winPending.UUID}, `Can't lock the host because it doesn't have MDM turned on.`},
-		{appCfgMacMDM, "valid macos but pending", []string{"--host", macPending.UUID}, `Can't lock the host because it doesn't have MDM turned on.`},
-		{appCfgAllMDM, "valid windows but pending unlock", []string{"--host", winEnrolledUP.UUID}, "Host has pending unlock request."},
-		{appCfgAllMDM, "valid macos but pending unlock", []string{"--host", macEnrolledUP.UUID}, "Host has pending unlock request."},
-		{appCfgAllMDM, "valid windows but pending lock", []string{"--host", winEnrolledLP.UUID}, "Host has pending lock request."},
-		{appCfgAllMDM, "valid macos but pending lock", []string{"--host", macEnrolledLP.UUID}, "Host has pending lock request."},
-		{appCfgAllMDM, "valid windows but pending wipe", []string{"--host", winEnrolledWP.UUID}, "Host has pending wipe request."},
-		{appCfgAllMDM, "valid macos but pending wipe", []string{"--host", macEnrolledWP.UUID}, "Host has pending wipe request."},
+		{appCfgMacMDM, "valid windows but only macos mdm", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, `Windows MDM isn't turned on.`},
+		{appCfgWinMDM, "valid macos but only windows mdm", []string{"--host", macEnrolled.UUID, "--confirmation", macEnrolled.LockWipeConfirmationToken()}, `macOS MDM isn't turned on.`},
+		{appCfgAllMDM, "valid windows", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, ""},
+		{appCfgAllMDM, "valid macos", []string{"--host", macEnrolled.UUID, "--confirmation", macEnrolled.LockWipeConfirmationToken()}, ""},
+		{appCfgAllMDM, "valid macos as argument", []string{"--confirmation", macEnrolled.LockWipeConfirmationToken(), macEnrolled.UUID}, ""},
+		{appCfgNoMDM, "valid linux", []string{"--host", linuxEnrolled.UUID, "--confirmation", linuxEnrolled.LockWipeConfirmationToken()}, ""},
+		{appCfgNoMDM, "valid windows but no mdm", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, `Windows MDM isn't turned on.`},
+		{appCfgNoMDM, "valid macos but no mdm", []string{"--host", macEnrolled.UUID, "--confirmation", macEnrolled.LockWipeConfirmationToken()}, `macOS MDM isn't turned on.`},
+		{appCfgMacMDM, "valid macos but not enrolled", []string{"--host", macNotEnrolled.UUID, "--confirmation", macNotEnrolled.LockWipeConfirmationToken()}, `Can't lock the host because it doesn't have MDM turned on.`},
+		{appCfgWinMDM, "valid windows but not enrolled", []string{"--host", winNotEnrolled.UUID, "--confirmation", winNotEnrolled.LockWipeConfirmationToken()}, `Can't lock the host because it doesn't have MDM turned on.`},
+		{appCfgWinMDM, "valid windows but pending ", []string{"--host", winPending.UUID, "--confirmation", winPending.LockWipeConfirmationToken()}, `Can't lock the host because it doesn't have MDM turned on.`},
+		{appCfgMacMDM, "valid macos but pending", []string{"--host", macPending.UUID, "--confirmation", macPending.LockWipeConfirmationToken()}, `Can't lock the host because it doesn't have MDM turned on.`},
+		{appCfgAllMDM, "valid windows but pending unlock", []string{"--host", winEnrolledUP.UUID, "--confirmation", winEnrolledUP.LockWipeConfirmationToken()}, "Host has pending unlock request."},
+		{appCfgAllMDM, "valid macos but pending unlock", []string{"--host", macEnrolledUP.UUID, "--confirmation", macEnrolledUP.LockWipeConfirmationToken()}, "Host has pending unlock request."},
+		{appCfgAllMDM, "valid windows but pending lock", []string{"--host", winEnrolledLP.UUID, "--confirmation", winEnrolledLP.LockWipeConfirmationToken()}, "Host has pending lock request."},
+		{appCfgAllMDM, "valid macos but pending lock", []string{"--host", macEnrolledLP.UUID, "--confirmation", macEnrolledLP.LockWipeConfirmationToken()}, "Host has pending lock request."},
+		{appCfgAllMDM, "valid windows but pending wipe", []string{"--host", winEnrolledWP.UUID, "--confirmation", winEnrolledWP.LockWipeConfirmationToken()}, "Host has pending wipe request."},
+		{appCfgAllMDM, "valid macos but pending wipe", []string{"--host", macEnrolledWP.UUID, "--confirmation", macEnrolledWP.LockWipeConfirmationToken()}, "Host has pending wipe request."},
 	}
 
 	runTestCases(t, ds, "lock", successfulOutput, cases)
+
+	// the command to get the unlock PIN is only suggested for macOS hosts
+	buf, err := runAppNoChecks([]string{"mdm", "lock", "--host", macEnrolled.UUID, "--confirmation", macEnrolled.LockWipeConfirmationToken()})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), fmt.Sprintf("fleetctl mdm unlock --host=%s --pin-only", macEnrolled.UUID))
+	buf, err = runAppNoChecks([]string{"mdm", "lock", "--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()})
+	require.NoError(t, err)
+	require.NotContains(t, buf.String(), "--pin-only")
+
+	// the lock must be confirmed with the serial number or confirmation token
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appCfgAllMDM, nil
+	}
+	_, err = runAppNoChecks([]string{"mdm", "lock", "--host", macEnrolled.UUID})
+	require.ErrorContains(t, err, "To lock the host, confirm with the host's serial number or the confirmation token: "+macEnrolled.LockWipeConfirmationToken())
+	_, err = runAppNoChecks([]string{"mdm", "lock", "--host", macEnrolled.UUID, "--confirmation", "wrong"})
+	require.ErrorContains(t, err, "To lock the host, confirm with the host's serial number")
+
+	// when a second approver is required, the first request waits for approval
+	appCfgApproval := *appCfgAllMDM
+	appCfgApproval.LockWipeSettings.RequireSecondApprover = true
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &appCfgApproval, nil
+	}
+	var approvalReq *fleet.HostLockWipeApprovalRequest
+	ds.GetHostLockWipeApprovalRequestFunc = func(ctx context.Context, hostID uint, action string) (*fleet.HostLockWipeApprovalRequest, error) {
+		if approvalReq == nil {
+			return nil, &notFoundError{}
+		}
+		return approvalReq, nil
+	}
+	ds.NewHostLockWipeApprovalRequestFunc = func(ctx context.Context, hostID uint, action string, userID uint) error {
+		approvalReq = &fleet.HostLockWipeApprovalRequest{HostID: hostID, Action: action, RequestedByUserID: &userID, CreatedAt: time.Now()}
+		return nil
+	}
+	ds.ClaimHostLockWipeApprovalRequestFunc = func(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error) {
+		if approvalReq == nil || approvalReq != req {
+			return false, nil
+		}
+		approvalReq = nil
+		return true, nil
+	}
+	ds.LockHostViaScriptFuncInvoked = false
+	lockFlags := []string{"mdm", "lock", "--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}
+	buf, err = runAppNoChecks(lockFlags)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "The lock request must be approved by another user.")
+	require.NotNil(t, approvalReq)
+	require.False(t, ds.LockHostViaScriptFuncInvoked)
+
+	// the same user can't approve the request
+	_, err = runAppNoChecks(lockFlags)
+	require.ErrorContains(t, err, "The request must be approved by a different user.")
+	require.False(t, ds.LockHostViaScriptFuncInvoked)
+
+	// another user approves the request
+	approvalReq.RequestedByUserID = ptr.Uint(*approvalReq.RequestedByUserID + 1)
+	buf, err = runAppNoChecks(lockFlags)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "The host will lock when it comes online.")
+	require.Nil(t, approvalReq)
+	require.True(t, ds.LockHostViaScriptFuncInvoked)
 }
 
 func TestMDMUnlockCommand(t *testing.T) {
@@ -690,6 +772,7 @@ func TestMDMUnlockCommand(t *testing.T) {
 		status.HostFleetPlatform = fleetPlatform
 		if _, ok := locked[host.ID]; ok {
 			if fleetPlatform == "darwin" {
+				status.UnlockPIN = "123456"
 				status.LockMDMCommand = &fleet.MDMCommand{}
 				status.LockMDMCommandResult = &fleet.MDMCommandResult{Status: fleet.MDMAppleStatusAcknowledged}
 				return &status, nil
@@ -710,6 +793,7 @@ func TestMDMUnlockCommand(t *testing.T) {
 
 		if _, ok := lockPending[host.ID]; ok {
 			if fleetPlatform == "darwin" {
+				status.UnlockPIN = "123456"
 				status.LockMDMCommand = &fleet.MDMCommand{}
 				return &status, nil
 			}
@@ -737,6 +821,9 @@ func TestMDMUnlockCommand(t *testing.T) {
 		return nil
 	}
 
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, hostUUID string) (*fleet.NanoEnrollment, error) {
+		return nil, nil
+	}
 	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
 		h, ok := hostsByID[hostID]
 		if !ok || h.MDMInfo == nil {
@@ -784,12 +871,17 @@ fleetctl get host %s
 		flags   []string
 		wantErr string
 	}{
-		{appCfgAllMDM, "no flags", nil, `Required flag "host" not set`},
+		{appCfgAllMDM, "no flags", nil, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "host flag empty", []string{"--host", ""}, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "unlock non-existent host", []string{"--host", "notfound"}, `The host doesn't exist. Please provide a valid host identifier.`},
 		{appCfgMacMDM, "valid windows but only macos mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
 		{appCfgAllMDM, "valid windows", []string{"--host", winEnrolled.UUID}, ""},
 		{appCfgAllMDM, "valid macos", []string{"--host", macEnrolled.UUID}, ""},
+		{appCfgAllMDM, "valid macos as argument", []string{macEnrolled.UUID}, ""},
+		{appCfgAllMDM, "valid macos pin only", []string{"--host", macEnrolled.UUID, "--pin-only"}, ""},
+		{appCfgAllMDM, "valid macos but pending lock pin only", []string{"--host", macEnrolledLP.UUID, "--pin-only"}, ""},
+		{appCfgAllMDM, "valid macos but pending wipe pin only", []string{"--host", macEnrolledWP.UUID, "--pin-only"}, "Host is not locked."},
+		{appCfgAllMDM, "valid windows pin only", []string{"--host", winEnrolled.UUID, "--pin-only"}, "The --pin-only flag is only supported for macOS hosts."},
 		{appCfgNoMDM, "valid linux", []string{"--host", linuxEnrolled.UUID}, ""},
 		{appCfgNoMDM, "valid windows but no mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
 		// TODO: should we error here?
@@ -807,6 +899,20 @@ fleetctl get host %s
 	}
 
 	runTestCases(t, ds, "unlock", successfulOutput, cases)
+
+	// retrieving the PIN does not request the host to be unlocked but is
+	// recorded as an activity
+	ds.UnlockHostManuallyFuncInvoked = false
+	var activityName string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activityName = activity.ActivityName()
+		return nil
+	}
+	buf, err := runAppNoChecks([]string{"mdm", "unlock", "--host", macEnrolled.UUID, "--pin-only"})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "123456")
+	require.False(t, ds.UnlockHostManuallyFuncInvoked)
+	require.Equal(t, fleet.ActivityTypeReadHostUnlockPIN{}.ActivityName(), activityName)
 }
 
 func TestMDMWipeCommand(t *testing.T) {
@@ -1075,6 +1181,9 @@ func TestMDMWipeCommand(t *testing.T) {
 		return nil
 	}
 
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, hostUUID string) (*fleet.NanoEnrollment, error) {
+		return nil, nil
+	}
 	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
 		h, ok := hostsByID[hostID]
 		if !ok || h.MDMInfo == nil {
@@ -1113,32 +1222,33 @@ func TestMDMWipeCommand(t *testing.T) {
 		flags   []string
 		wantErr string
 	}{
-		{appCfgAllMDM, "no flags", nil, `Required flag "host" not set`},
+		{appCfgAllMDM, "no flags", nil, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "host flag empty", []string{"--host", ""}, `No host targeted. Please provide --host.`},
 		{appCfgAllMDM, "wipe non-existent host", []string{"--host", "notfound"}, `The host doesn't exist. Please provide a valid host identifier.`},
-		{appCfgMacMDM, "valid windows but only macos mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
-		{appCfgAllMDM, "valid windows", []string{"--host", winEnrolled.UUID}, ""},
-		{appCfgAllMDM, "valid macos", []string{"--host", macEnrolled.UUID}, ""},
-		{appCfgNoMDM, "valid linux", []string{"--host", linuxEnrolled.UUID}, ""},
-		{appCfgNoMDM, "valid linux 2", []string{"--host", linuxEnrolled2.UUID}, ""},
-		{appCfgNoMDM, "valid linux 3", []string{"--host", linuxEnrolled3.UUID}, ""},
-		{appCfgNoMDM, "valid windows but no mdm", []string{"--host", winEnrolled.UUID}, `Windows MDM isn't turned on.`},
-		{appCfgMacMDM, "valid macos but not enrolled", []string{"--host", macNotEnrolled.UUID}, `Can't wipe the host because it doesn't have MDM turned on.`},
-		{appCfgWinMDM, "valid windows but not enrolled", []string{"--host", winNotEnrolled.UUID}, `Can't wipe the host because it doesn't have MDM turned on.`},
-		{appCfgWinMDM, "valid windows but pending mdm enroll", []string{"--host", winPending.UUID}, `Can't wipe the host because it doesn't have MDM turned on.`},
-		{appCfgMacMDM, "valid macos but pending mdm enroll", []string{"--host", macPending.UUID}, `Can't wipe the host because it doesn't have MDM turned on.`},
-		{appCfgAllMDM, "valid windows but pending unlock", []string{"--host", winEnrolledUP.UUID}, "Host has pending unlock request."},
-		{appCfgAllMDM, "valid macos but pending unlock", []string{"--host", macEnrolledUP.UUID}, "Host has pending unlock request."},
-		{appCfgAllMDM, "valid windows but pending lock", []string{"--host", winEnrolledLP.UUID}, "Host has pending lock request."},
-		{appCfgAllMDM, "valid macos but pending lock", []string{"--host", macEnrolledLP.UUID}, "Host has pending lock request."},
-		{appCfgAllMDM, "valid windows but pending wipe", []string{"--host", winEnrolledWP.UUID}, "Host has pending wipe request."},
-		{appCfgAllMDM, "valid macos but pending wipe", []string{"--host", macEnrolledWP.UUID}, "Host has pending wipe request."},
-		{appCfgAllMDM, "valid windows but host wiped", []string{"--host", winEnrolledWiped.UUID}, "Host is already wiped."},
-		{appCfgAllMDM, "valid macos but host wiped", []string{"--host", macEnrolledWiped.UUID}, "Host is already wiped."},
-		{appCfgAllMDM, "valid windows but host is locked", []string{"--host", winEnrolledLocked.UUID}, "Host cannot be wiped until it is unlocked."},
-		{appCfgAllMDM, "valid macos but host is locked", []string{"--host", macEnrolledLocked.UUID}, "Host cannot be wiped until it is unlocked."},
-		{appCfgAllMDM, "valid macos but host is locked", []string{"--host", macEnrolledLocked.UUID}, "Host cannot be wiped until it is unlocked."},
-		{appCfgScriptsDisabled, "valid linux but script are disabled", []string{"--host", linuxEnrolled.UUID}, "Can't wipe host because running scripts is disabled in organization settings."},
+		{appCfgMacMDM, "valid windows but only macos mdm", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, `Windows MDM isn't turned on.`},
+		{appCfgAllMDM, "valid windows", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, ""},
+		{appCfgAllMDM, "valid macos", []string{"--host", macEnrolled.UUID, "--confirmation", macEnrolled.LockWipeConfirmationToken()}, ""},
+		// the linux hosts share the same UUID, the host found is the last one registered
+		{appCfgNoMDM, "valid linux", []string{"--host", linuxEnrolled.UUID, "--confirmation", hostByUUID[linuxEnrolled.UUID].LockWipeConfirmationToken()}, ""},
+		{appCfgNoMDM, "valid linux 2", []string{"--host", linuxEnrolled2.UUID, "--confirmation", hostByUUID[linuxEnrolled2.UUID].LockWipeConfirmationToken()}, ""},
+		{appCfgNoMDM, "valid linux 3", []string{"--host", linuxEnrolled3.UUID, "--confirmation", hostByUUID[linuxEnrolled3.UUID].LockWipeConfirmationToken()}, ""},
+		{appCfgNoMDM, "valid windows but no mdm", []string{"--host", winEnrolled.UUID, "--confirmation", winEnrolled.LockWipeConfirmationToken()}, `Windows MDM isn't turned on.`},
+		{appCfgMacMDM, "valid macos but not enrolled", []string{"--host", macNotEnrolled.UUID, "--confirmation", macNotEnrolled.LockWipeConfirmationToken()}, `Can't wipe the host because it doesn't have MDM turned on.`},
+		{appCfgWinMDM, "valid windows but not enrolled", []string{"--host", winNotEnrolled.UUID, "--confirmation", winNotEnrolled.LockWipeConfirmationToken()}, `Can't wipe the host because it doesn't have MDM turned on.`},
+		{appCfgWinMDM, "valid windows but pending mdm enroll", []string{"--host", winPending.UUID, "--confirmation", winPending.LockWipeConfirmationToken()}, `Can't wipe the host because it doesn't have MDM turned on.`},
+		{appCfgMacMDM, "valid macos but pending mdm enroll", []string{"--host", macPending.UUID, "--confirmation", macPending.LockWipeConfirmationToken()}, `Can't wipe the host because it doesn't have MDM turned on.`},
+		{appCfgAllMDM, "valid windows but pending unlock", []string{"--host", winEnrolledUP.UUID, "--confirmation", winEnrolledUP.LockWipeConfirmationToken()}, "Host has pending unlock request."},
+		{appCfgAllMDM, "valid macos but pending unlock", []string{"--host", macEnrolledUP.UUID, "--confirmation", macEnrolledUP.LockWipeConfirmationToken()}, "Host has pending unlock request."},
+		{appCfgAllMDM, "valid windows but pending lock", []string{"--host", winEnrolledLP.UUID, "--confirmation", winEnrolledLP.LockWipeConfirmationToken()}, "Host has pending lock request."},
+		{appCfgAllMDM, "valid macos but pending lock", []string{"--host", macEnrolledLP.UUID, "--confirmation", macEnrolledLP.LockWipeConfirmationToken()}, "Host has pending lock request."},
+		{appCfgAllMDM, "valid windows but pending wipe", []string{"--host", winEnrolledWP.UUID, "--confirmation", winEnrolledWP.LockWipeConfirmationToken()}, "Host has pending wipe request."},
+		{appCfgAllMDM, "valid macos but pending wipe", []string{"--host", macEnrolledWP.UUID, "--confirmation", macEnrolledWP.LockWipeConfirmationToken()}, "Host has pending wipe request."},
+		{appCfgAllMDM, "valid windows but host wiped", []string{"--host", winEnrolledWiped.UUID, "--confirmation", winEnrolledWiped.LockWipeConfirmationToken()}, "Host is already wiped."},
+		{appCfgAllMDM, "valid macos but host wiped", []string{"--host", macEnrolledWiped.UUID, "--confirmation", macEnrolledWiped.LockWipeConfirmationToken()}, "Host is already wiped."},
+		{appCfgAllMDM, "valid windows but host is locked", []string{"--host", winEnrolledLocked.UUID, "--confirmation", winEnrolledLocked.LockWipeConfirmationToken()}, "Host cannot be wiped until it is unlocked."},
+		{appCfgAllMDM, "valid macos but host is locked", []string{"--host", macEnrolledLocked.UUID, "--confirmation", macEnrolledLocked.LockWipeConfirmationToken()}, "Host cannot be wiped until it is unlocked."},
+		{appCfgAllMDM, "valid macos but host is locked", []string{"--host", macEnrolledLocked.UUID, "--confirmation", macEnrolledLocked.LockWipeConfirmationToken()}, "Host cannot be wiped until it is unlocked."},
+		{appCfgScriptsDisabled, "valid linux but script are disabled", []string{"--host", linuxEnrolled.UUID, "--confirmation", linuxEnrolled.LockWipeConfirmationToken()}, "Can't wipe host because running scripts is disabled in organization settings."},
 	}
 
 	successfulOutput := func(ident string) string {
@@ -1257,6 +1367,12 @@ func setupDSMocks(ds *mock.Store, hostByUUID map[string]*fleet.Host, hostsByID m
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, &notFoundError{}
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, &notFoundError{}
+	}
 	ds.HostLiteFunc = func(ctx context.Context, hostID uint) (*fleet.Host, error) {
 		h, ok := hostsByID[hostID]
 		if !ok {
@@ -1309,7 +1425,64 @@ func runTestCases(t *testing.T, ds *mock.Store, actionType string, successfulOut
 			require.ErrorContains(t, err, c.wantErr, c.desc)
 		} else {
 			require.NoError(t, err, c.desc)
-			require.Contains(t, buf.String(), successfulOutput(c.flags[1]), c.desc)
+			// the host is provided either via the --host flag or as last argument
+			ident := c.flags[len(c.flags)-1]
+			if c.flags[0] == "--host" {
+				ident = c.flags[1]
+			}
+			require.Contains(t, buf.String(), successfulOutput(ident), c.desc)
 		}
 	}
 }
+
+func TestMDMAssetsCommands(t *testing.T) {
+	testCert, testKey, err := apple_mdm.NewSCEPCACertKey()
+	require.NoError(t, err)
+	testCertPEM := tokenpki.PEMCertificate(testCert.Raw)
+	testKeyPEM := tokenpki.PEMRSAPrivateKey(testKey)
+	cfg := config.TestConfig()
+	cfg.MDM.AppleAPNsCertBytes = string(testCertPEM)
+	cfg.MDM.AppleAPNsKeyBytes = string(testKeyPEM)
+
+	_, ds := runServerWithMockedDS(t, &service.TestServerOpts{
+		License:     &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)},
+		FleetConfig: &cfg,
+	})
+	ds.ListTenantsFunc = func(ctx context.Context) ([]*fleet.Tenant, error) {
+		return nil, nil
+	}
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return nil, nil
+	}
+	ds.GetMDMAppleBootstrapPackageMetaFunc = func(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackage, error) {
+		return nil, &notFoundError{}
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	const passphrase = "correct horse battery staple"
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "assets.bundle")
+
+	runAppCheckErr(t, []string{"mdm", "assets", "export", "--output", bundlePath}, `Required flag "passphrase" not set`)
+	_, err = runAppNoChecks([]string{"mdm", "assets", "export", "--output", bundlePath, "--passphrase", "short"})
+	require.ErrorContains(t, err, "must be at least 12 characters")
+
+	out := runAppForTest(t, []string{"mdm", "assets", "export", "--output", bundlePath, "--passphrase", passphrase})
+	require.Contains(t, out, "MDM assets exported to "+bundlePath)
+	info, err := os.Stat(bundlePath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	extractDir := filepath.Join(dir, "extracted")
+	out = runAppForTest(t, []string{"mdm", "assets", "import", "--bundle", bundlePath, "--extract-dir", extractDir, "--passphrase", passphrase})
+	require.Contains(t, out, "mdm.apple_apns_cert: "+filepath.Join(extractDir, "apns_cert.pem"))
+	require.Contains(t, out, "[!] skipped asset apns_key: set in the server configuration")
+	b, err := os.ReadFile(filepath.Join(extractDir, "apns_key.pem"))
+	require.NoError(t, err)
+	require.Equal(t, testKeyPEM, b)
+
+	_, err = runAppNoChecks([]string{"mdm", "assets", "import", "--bundle", bundlePath, "--passphrase", "wrong passphrase!"})
+	require.ErrorContains(t, err, "check the passphrase")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	policyIDFlagName  = "policy-id"
+	startDateFlagName = "start-date"
+	endDateFlagName   = "end-date"
+)
+
+func reportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "report",
+		Usage:     "Report on the history of the Fleet instance",
+		UsageText: `fleetctl report <command> [options]`,
+		Subcommands: []*cli.Command{
+			reportPolicyComplianceCommand(),
+		},
+	}
+}
+
+func reportPolicyComplianceCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "policy-compliance",
+		Usage: "Report the daily number of hosts passing and failing policies",
+		UsageText: `fleetctl report policy-compliance [options]
+
+   The counts are those of the global policies on all hosts, or of the team's
+   policies and inherited global policies on the hosts of the team if --team
+   is set. By default, the report covers the last 30 days.`,
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  teamFlagName,
+				Usage: "Report on the hosts and policies of the team with this ID",
+			},
+			&cli.UintFlag{
+				Name:  policyIDFlagName,
+				Usage: "Report on the policy with this ID only",
+			},
+			&cli.StringFlag{
+				Name:  startDateFlagName,
+				Usage: "First day of the report, in the YYYY-MM-DD format",
+			},
+			&cli.StringFlag{
+				Name:  endDateFlagName,
+				Usage: "Last day of the report, in the YYYY-MM-DD format (default: today)",
+			},
+			jsonFlag(),
+			yamlFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			var teamID, policyID *uint
+			if c.IsSet(teamFlagName) {
+				tid := c.Uint(teamFlagName)
+				teamID = &tid
+			}
+			if c.IsSet(policyIDFlagName) {
+				pid := c.Uint(policyIDFlagName)
+				policyID = &pid
+			}
+
+			trends, err := client.GetPolicyComplianceTrends(teamID, policyID, c.String(startDateFlagName), c.String(endDateFlagName))
+			if err != nil {
+				return fmt.Errorf("could not get policy compliance trends: %w", err)
+			}
+
+			if c.Bool(jsonFlagName) {
+				return printJSON(trends, c.App.Writer)
+			}
+			if c.Bool(yamlFlagName) {
+				return printYaml(trends, c.App.Writer)
+			}
+
+			if len(trends.Days) == 0 {
+				fmt.Fprintf(c.App.Writer, "No policy compliance recorded between %s and %s.\n",
+					trends.StartDate.Format("2006-01-02"), trends.EndDate.Format("2006-01-02"))
+				return nil
+			}
+
+			table := defaultTable(c.App.Writer)
+			table.SetHeader([]string{"date", "passing", "failing", "compliance %"})
+			for _, day := range trends.Days {
+				compliance := "-"
+				if total := day.PassingHostCount + day.FailingHostCount; total > 0 {
+					compliance = strconv.FormatFloat(100*float64(day.PassingHostCount)/float64(total), 'f', 1, 64)
+				}
+				table.Append([]string{
+					day.Date.Format("2006-01-02"),
+					strconv.FormatUint(uint64(day.PassingHostCount), 10),
+					strconv.FormatUint(uint64(day.FailingHostCount), 10),
+					compliance,
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportPolicyCompliance(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	day2 := time.Now().UTC().Truncate(24 * time.Hour)
+	day1 := day2.AddDate(0, 0, -1)
+	start, end := day1.Format("2006-01-02"), day2.Format("2006-01-02")
+	var gotOpts fleet.PolicyComplianceTrendsOptions
+	ds.GetPolicyComplianceTrendsFunc = func(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) ([]fleet.PolicyComplianceDay, error) {
+		gotOpts = opts
+		if opts.PolicyID != nil {
+			return []fleet.PolicyComplianceDay{}, nil
+		}
+		return []fleet.PolicyComplianceDay{
+			{Date: day1, PassingHostCount: 3, FailingHostCount: 1},
+			{Date: day2, PassingHostCount: 0, FailingHostCount: 0},
+		}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+
+	expected := fmt.Sprintf(`+------------+---------+---------+--------------+
+|    DATE    | PASSING | FAILING | COMPLIANCE %% |
++------------+---------+---------+--------------+
+| %s |       3 |       1 |         75.0 |
++------------+---------+---------+--------------+
+| %s |       0 |       0 | -            |
++------------+---------+---------+--------------+
+`, start, end)
+	require.Equal(t, expected, runAppForTest(t, []string{"report", "policy-compliance", "--team", "1", "--start-date", start, "--end-date", end}))
+	require.NotNil(t, gotOpts.TeamID)
+	require.Equal(t, uint(1), *gotOpts.TeamID)
+	require.Nil(t, gotOpts.PolicyID)
+	require.Equal(t, day1, gotOpts.StartDate)
+	require.Equal(t, day2, gotOpts.EndDate)
+
+	require.Equal(t,
+		fmt.Sprintf(`{"team_id":null,"policy_id":null,"start_date":"%[1]sT00:00:00Z","end_date":"%[2]sT00:00:00Z","days":[{"date":"%[1]sT00:00:00Z","passing_host_count":3,"failing_host_count":1},{"date":"%[2]sT00:00:00Z","passing_host_count":0,"failing_host_count":0}]}
+`, start, end),
+		runAppForTest(t, []string{"report", "policy-compliance", "--start-date", start, "--end-date", end, "--json"}),
+	)
+	require.Nil(t, gotOpts.TeamID)
+
+	require.Equal(t, fmt.Sprintf("No policy compliance recorded between %s and %s.\n", start, end),
+		runAppForTest(t, []string{"report", "policy-compliance", "--policy-id", "2", "--start-date", start, "--end-date", end}))
+	require.NotNil(t, gotOpts.PolicyID)
+	require.Equal(t, uint(2), *gotOpts.PolicyID)
+
+	runAppCheckErr(t, []string{"report", "policy-compliance", "--start-date", "20240620"},
+		"could not get policy compliance trends: GET /api/latest/fleet/policies/trends received status 422 Validation Failed: must be a date in the YYYY-MM-DD format")
+}
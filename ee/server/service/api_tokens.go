@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+func (svc *Service) NewAPIToken(ctx context.Context, payload fleet.APITokenPayload) (*fleet.APIToken, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.APIToken{TeamID: payload.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := payload.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate api token")
+	}
+
+	team, err := svc.ds.Team(ctx, payload.TeamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get api token team")
+	}
+
+	vc := authz.UserFromContext(ctx)
+	if vc == nil {
+		return nil, fleet.ErrNoContext
+	}
+
+	secret, err := server.GenerateRandomText(svc.config.App.TokenKeySize)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate api token")
+	}
+
+	tok, err := svc.ds.NewAPIToken(ctx, &fleet.APIToken{
+		Name:            payload.Name,
+		TeamID:          team.ID,
+		Scope:           payload.Scope,
+		CreatedByUserID: vc.ID,
+	}, fleet.HashAPITokenSecret(secret))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create api token")
+	}
+	tok.Token = secret
+
+	if err := svc.ds.NewActivity(ctx, vc, fleet.ActivityTypeCreatedAPIToken{
+		TokenName: tok.Name,
+		Scope:     tok.Scope,
+		TeamID:    team.ID,
+		TeamName:  team.Name,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for new api token")
+	}
+
+	return tok, nil
+}
+
+func (svc *Service) ListAPITokens(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error) {
+	authzObj := &fleet.APIToken{}
+	if teamID != nil {
+		authzObj.TeamID = *teamID
+	}
+	if err := svc.authz.Authorize(ctx, authzObj, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	toks, err := svc.ds.ListAPITokens(ctx, teamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list api tokens")
+	}
+	return toks, nil
+}
+
+func (svc *Service) DeleteAPIToken(ctx context.Context, id uint) error {
+	tok, err := svc.ds.APIToken(ctx, id)
+	if err != nil {
+		svc.authz.SkipAuthorization(ctx)
+		return ctxerr.Wrap(ctx, err, "get api token")
+	}
+	if err := svc.authz.Authorize(ctx, tok, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	team, err := svc.ds.Team(ctx, tok.TeamID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get api token team")
+	}
+
+	if err := svc.ds.DeleteAPIToken(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete api token")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDeletedAPIToken{
+		TokenName: tok.Name,
+		Scope:     tok.Scope,
+		TeamID:    team.ID,
+		TeamName:  team.Name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for deleted api token")
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ package service
 
 import (
 	"context"
+	"html/template"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
 	"github.com/go-kit/kit/log/level"
 )
 
@@ -78,6 +80,132 @@ func (svc *Service) TriggerMigrateMDMDevice(ctx context.Context, host *fleet.Hos
 		return ctxerr.Wrap(ctx, err, "save host with refetch critical queries timestamp")
 	}
 
+	// the migration has been triggered, let the end user know about it. This is
+	// best-effort, failing to send the email does not fail the migration.
+	if err := svc.sendMDMMigrationEmail(ctx, ac, host); err != nil {
+		level.Error(svc.logger).Log("msg", "failed to send MDM migration email", "host_id", host.ID, "err", err)
+	}
+
+	return nil
+}
+
+// sendMDMMigrationEmail sends the MDM migration email to the end user of the
+// host, as identified by the IdP account used to enroll it. Nothing is sent
+// if SMTP is not configured or if the host has no such end user.
+func (svc *Service) sendMDMMigrationEmail(ctx context.Context, ac *fleet.AppConfig, host *fleet.Host) error {
+	if ac.SMTPSettings == nil || !ac.SMTPSettings.SMTPConfigured {
+		return nil
+	}
+
+	mappings, err := svc.ds.ListHostDeviceMapping(ctx, host.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list host device mapping")
+	}
+	var to []string
+	for _, m := range mappings {
+		if m.Source == fleet.DeviceMappingMDMIdpAccounts {
+			to = append(to, m.Email)
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	tmpl, err := svc.ds.EmailTemplate(ctx, fleet.EmailTemplateMDMMigration)
+	if err != nil {
+		if !fleet.IsNotFound(err) {
+			return ctxerr.Wrap(ctx, err, "get MDM migration email template")
+		}
+		tmpl = &fleet.EmailTemplate{Name: fleet.EmailTemplateMDMMigration}
+	}
+
+	e := fleet.Email{
+		To:           to,
+		ServerURL:    ac.ServerSettings.ServerURL,
+		SMTPSettings: *ac.SMTPSettings,
+		Mailer: &mail.MDMMigrationMailer{
+			BaseURL:         template.URL(ac.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+			AssetURL:        mail.AssetURL,
+			OrgName:         ac.OrgInfo.OrgName,
+			ContactURL:      ac.OrgInfo.ContactURL,
+			HostDisplayName: host.DisplayName(),
+			CustomTemplate:  tmpl.Body,
+		},
+	}
+	tmpl.ApplyTo(&e)
+	if err := svc.mailService.SendEmail(e); err != nil {
+		return ctxerr.Wrap(ctx, err, "send MDM migration email")
+	}
+	return nil
+}
+
+// sendLockWipeNotificationEmail notifies the end user of the host that the
+// host was locked, unlocked or wiped. The end user is identified by the IdP
+// account used to enroll the host or the custom email assigned to it. Nothing
+// is sent if the notification is disabled for the host's team (or no team), if
+// SMTP is not configured or if the host has no such end user.
+func (svc *Service) sendLockWipeNotificationEmail(ctx context.Context, host *fleet.Host, action string) error {
+	settings, err := svc.hostLockWipeSettings(ctx, host)
+	if err != nil {
+		return err
+	}
+	if !settings.NotifyEndUser {
+		return nil
+	}
+
+	ac, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if ac.SMTPSettings == nil || !ac.SMTPSettings.SMTPConfigured {
+		return nil
+	}
+
+	mappings, err := svc.ds.ListHostDeviceMapping(ctx, host.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list host device mapping")
+	}
+	var to []string
+	seen := make(map[string]bool, len(mappings))
+	for _, m := range mappings {
+		if m.Source != fleet.DeviceMappingMDMIdpAccounts && m.Source != fleet.DeviceMappingCustomReplacement {
+			continue
+		}
+		if !seen[m.Email] {
+			seen[m.Email] = true
+			to = append(to, m.Email)
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	tmpl, err := svc.ds.EmailTemplate(ctx, fleet.EmailTemplateLockWipeNotification)
+	if err != nil {
+		if !fleet.IsNotFound(err) {
+			return ctxerr.Wrap(ctx, err, "get lock/wipe notification email template")
+		}
+		tmpl = &fleet.EmailTemplate{Name: fleet.EmailTemplateLockWipeNotification}
+	}
+
+	e := fleet.Email{
+		To:           to,
+		ServerURL:    ac.ServerSettings.ServerURL,
+		SMTPSettings: *ac.SMTPSettings,
+		Mailer: &mail.LockWipeNotificationMailer{
+			BaseURL:         template.URL(ac.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+			AssetURL:        mail.AssetURL,
+			OrgName:         ac.OrgInfo.OrgName,
+			ContactURL:      ac.OrgInfo.ContactURL,
+			HostDisplayName: host.DisplayName(),
+			Action:          action,
+			CustomTemplate:  tmpl.Body,
+		},
+	}
+	tmpl.ApplyTo(&e)
+	if err := svc.mailService.SendEmail(e); err != nil {
+		return ctxerr.Wrap(ctx, err, "send lock/wipe notification email")
+	}
 	return nil
 }
 
@@ -115,14 +243,43 @@ func (svc *Service) GetFleetDesktopSummary(ctx context.Context) (fleet.DesktopSu
 		}
 	}
 
-	// organization information
-	sum.Config.OrgInfo.OrgName = appCfg.OrgInfo.OrgName
-	sum.Config.OrgInfo.OrgLogoURL = appCfg.OrgInfo.OrgLogoURL
-	sum.Config.OrgInfo.OrgLogoURLLightBackground = appCfg.OrgInfo.OrgLogoURLLightBackground
-	sum.Config.OrgInfo.ContactURL = appCfg.OrgInfo.ContactURL
+	var team *fleet.Team
+	if host.TeamID != nil {
+		team, err = svc.ds.Team(ctx, *host.TeamID)
+		if err != nil {
+			return sum, ctxerr.Wrap(ctx, err, "retrieving host team")
+		}
+	}
+
+	// organization information, with the branding of the host's tenant and
+	// team
+	orgCfg := appCfg
+	if team != nil && team.TenantID != nil {
+		orgCfg, err = svc.ds.TenantAppConfig(ctx, *team.TenantID)
+		if err != nil {
+			return sum, ctxerr.Wrap(ctx, err, "retrieving tenant config")
+		}
+	}
+	branding := fleet.ResolveBranding(orgCfg, team)
+	sum.Config.OrgInfo.OrgName = branding.OrgName
+	sum.Config.OrgInfo.OrgLogoURL = branding.OrgLogoURL
+	sum.Config.OrgInfo.OrgLogoURLLightBackground = branding.OrgLogoURLLightBackground
+	sum.Config.OrgInfo.ContactURL = branding.ContactURL
+	sum.Config.OrgInfo.PrimaryColor = branding.PrimaryColor
+	sum.Config.OrgInfo.BackgroundColor = branding.BackgroundColor
 
 	// mdm information
 	sum.Config.MDM.MacOSMigration.Mode = appCfg.MDM.MacOSMigration.Mode
 
+	// localization of the end-user facing messages
+	sum.Config.Locale = fleet.ResolveLocale(appCfg, team)
+	if sum.Config.Locale != fleet.DefaultLocale {
+		translations, err := svc.ds.ListTranslations(ctx)
+		if err != nil {
+			return sum, ctxerr.Wrap(ctx, err, "retrieving translations")
+		}
+		sum.Config.Messages = fleet.NewLocalizedMessages(sum.Config.Locale, translations)
+	}
+
 	return sum, nil
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+type mailServiceFunc func(e fleet.Email) error
+
+func (f mailServiceFunc) SendEmail(e fleet.Email) error { return f(e) }
+
+func TestSendMDMMigrationEmail(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+
+	var sent []fleet.Email
+	svc := &Service{ds: ds, mailService: mailServiceFunc(func(e fleet.Email) error {
+		sent = append(sent, e)
+		return nil
+	})}
+
+	ac := &fleet.AppConfig{
+		OrgInfo:        fleet.OrgInfo{OrgName: "Acme", ContactURL: "https://acme.com/it"},
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.acme.com"},
+	}
+	host := &fleet.Host{ID: 1, ComputerName: "Jane's Mac"}
+
+	ds.ListHostDeviceMappingFunc = func(ctx context.Context, id uint) ([]*fleet.HostDeviceMapping, error) {
+		return []*fleet.HostDeviceMapping{
+			{HostID: id, Email: "jane@acme.com", Source: fleet.DeviceMappingMDMIdpAccounts},
+			{HostID: id, Email: "jane@gmail.com", Source: fleet.DeviceMappingGoogleChromeProfiles},
+		}, nil
+	}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		require.Equal(t, fleet.EmailTemplateMDMMigration, name)
+		return &fleet.EmailTemplate{
+			Name:       name,
+			Body:       `<p>{{.OrgName}} migrates {{.HostDisplayName}}, see {{.ContactURL}}</p>`,
+			SenderName: "Acme IT",
+		}, nil
+	}
+
+	// SMTP not configured, nothing is sent
+	require.NoError(t, svc.sendMDMMigrationEmail(ctx, ac, host))
+	require.Empty(t, sent)
+	require.False(t, ds.ListHostDeviceMappingFuncInvoked)
+
+	ac.SMTPSettings = &fleet.SMTPSettings{SMTPConfigured: true, SMTPSenderAddress: "fleet@acme.com"}
+	require.NoError(t, svc.sendMDMMigrationEmail(ctx, ac, host))
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"jane@acme.com"}, sent[0].To)
+	require.Equal(t, "Migrate your Mac to Fleet", sent[0].Subject)
+	require.Equal(t, "Acme IT", sent[0].SenderName)
+	require.Equal(t, "fleet@acme.com", sent[0].FromAddress())
+	body, err := sent[0].Mailer.Message()
+	require.NoError(t, err)
+	require.Equal(t, `<p>Acme migrates Jane&#39;s Mac, see https://acme.com/it</p>`, string(body))
+	require.IsType(t, &mail.MDMMigrationMailer{}, sent[0].Mailer)
+
+	// no end user email, nothing is sent
+	ds.ListHostDeviceMappingFunc = func(ctx context.Context, id uint) ([]*fleet.HostDeviceMapping, error) {
+		return nil, nil
+	}
+	require.NoError(t, svc.sendMDMMigrationEmail(ctx, ac, host))
+	require.Len(t, sent, 1)
+}
+
+func TestSendLockWipeNotificationEmail(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+
+	var sent []fleet.Email
+	svc := &Service{ds: ds, mailService: mailServiceFunc(func(e fleet.Email) error {
+		sent = append(sent, e)
+		return nil
+	})}
+
+	ac := &fleet.AppConfig{
+		OrgInfo:        fleet.OrgInfo{OrgName: "Acme"},
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.acme.com"},
+		SMTPSettings:   &fleet.SMTPSettings{SMTPConfigured: true, SMTPSenderAddress: "fleet@acme.com"},
+	}
+	teamSettings := fleet.LockWipeSettings{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return ac, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{LockWipeSettings: teamSettings}}, nil
+	}
+	ds.ListHostDeviceMappingFunc = func(ctx context.Context, id uint) ([]*fleet.HostDeviceMapping, error) {
+		return []*fleet.HostDeviceMapping{
+			{HostID: id, Email: "jane@acme.com", Source: fleet.DeviceMappingMDMIdpAccounts},
+			{HostID: id, Email: "jane@acme.com", Source: fleet.DeviceMappingCustomReplacement},
+			{HostID: id, Email: "john@acme.com", Source: fleet.DeviceMappingCustomReplacement},
+			{HostID: id, Email: "jane@gmail.com", Source: fleet.DeviceMappingGoogleChromeProfiles},
+		}, nil
+	}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		require.Equal(t, fleet.EmailTemplateLockWipeNotification, name)
+		return &fleet.EmailTemplate{
+			Name: name,
+			Body: `<p>{{.OrgName}} {{.Action}} {{.HostDisplayName}}</p>`,
+		}, nil
+	}
+
+	noTeamHost := &fleet.Host{ID: 1, ComputerName: "Jane's Mac"}
+	teamHost := &fleet.Host{ID: 2, ComputerName: "John's PC", TeamID: ptr.Uint(1)}
+
+	// notification disabled, nothing is sent
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, noTeamHost, mail.LockWipeActionLocked))
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, teamHost, mail.LockWipeActionLocked))
+	require.Empty(t, sent)
+	require.False(t, ds.ListHostDeviceMappingFuncInvoked)
+
+	// enabled for no team only
+	ac.LockWipeSettings.NotifyEndUser = true
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, teamHost, mail.LockWipeActionWiped))
+	require.Empty(t, sent)
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, noTeamHost, mail.LockWipeActionLocked))
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"jane@acme.com", "john@acme.com"}, sent[0].To)
+	require.Equal(t, "An action was taken on your device", sent[0].Subject)
+	body, err := sent[0].Mailer.Message()
+	require.NoError(t, err)
+	require.Equal(t, `<p>Acme locked Jane&#39;s Mac</p>`, string(body))
+
+	// enabled for the team
+	teamSettings.NotifyEndUser = true
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, teamHost, mail.LockWipeActionWiped))
+	require.Len(t, sent, 2)
+	body, err = sent[1].Mailer.Message()
+	require.NoError(t, err)
+	require.Equal(t, `<p>Acme wiped John&#39;s PC</p>`, string(body))
+
+	// SMTP not configured, nothing is sent
+	ac.SMTPSettings.SMTPConfigured = false
+	require.NoError(t, svc.sendLockWipeNotificationEmail(ctx, teamHost, mail.LockWipeActionUnlocked))
+	require.Len(t, sent, 2)
+}
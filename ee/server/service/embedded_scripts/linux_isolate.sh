@@ -0,0 +1,41 @@
+#!/bin/sh
+
+# Isolate the host from the network, only allowing the traffic with the Fleet
+# server (and DNS to resolve it) so that the host can still be managed and
+# released from isolation.
+
+FLEET_SERVER_HOST="{{ .FleetServerHost }}"
+CHAIN="FLEET_ISOLATION"
+
+if ! command -v iptables >/dev/null 2>&1; then
+    echo "iptables is required to isolate the host."
+    exit 1
+fi
+
+fleet_ips=$(getent ahostsv4 "$FLEET_SERVER_HOST" | awk '{ print $1 }' | sort -u)
+if [ -z "$fleet_ips" ]; then
+    echo "Could not resolve the Fleet server $FLEET_SERVER_HOST, not isolating the host."
+    exit 1
+fi
+
+# Create (or reset) the isolation chain
+iptables -N "$CHAIN" 2>/dev/null || iptables -F "$CHAIN"
+
+iptables -A "$CHAIN" -i lo -j ACCEPT
+iptables -A "$CHAIN" -o lo -j ACCEPT
+iptables -A "$CHAIN" -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+iptables -A "$CHAIN" -p udp --dport 53 -j ACCEPT
+iptables -A "$CHAIN" -p tcp --dport 53 -j ACCEPT
+for ip in $fleet_ips; do
+    iptables -A "$CHAIN" -d "$ip" -j ACCEPT
+done
+iptables -A "$CHAIN" -j DROP
+
+# Send all incoming and outgoing traffic through the isolation chain first
+for direction in INPUT OUTPUT; do
+    if ! iptables -C "$direction" -j "$CHAIN" 2>/dev/null; then
+        iptables -I "$direction" 1 -j "$CHAIN"
+    fi
+done
+
+echo "The host is isolated from the network, only the Fleet server $FLEET_SERVER_HOST is reachable."
@@ -0,0 +1,16 @@
+#!/bin/sh
+
+# Release the host from the network isolation applied by Fleet.
+
+CHAIN="FLEET_ISOLATION"
+
+for direction in INPUT OUTPUT; do
+    while iptables -C "$direction" -j "$CHAIN" 2>/dev/null; do
+        iptables -D "$direction" -j "$CHAIN"
+    done
+done
+
+iptables -F "$CHAIN" 2>/dev/null
+iptables -X "$CHAIN" 2>/dev/null
+
+echo "The host is no longer isolated from the network."
@@ -0,0 +1,26 @@
+#!/bin/sh
+
+# Isolate the host from the network, only allowing the traffic with the Fleet
+# server, DNS to resolve it and Apple's push notification service so that the
+# host can still be managed and released from isolation. The rules are loaded
+# in an anchor under com.apple, which the default pf configuration evaluates.
+
+FLEET_SERVER_HOST="{{ .FleetServerHost }}"
+ANCHOR="com.apple/999.FleetIsolation"
+RULES_FILE="/etc/pf.anchors/com.fleetdm.isolation"
+
+cat > "$RULES_FILE" <<RULES
+pass quick on lo0 all
+pass out quick proto { tcp, udp } to any port 53 keep state
+pass out quick proto tcp to $FLEET_SERVER_HOST keep state
+pass out quick proto tcp to 17.0.0.0/8 port { 443, 2197, 5223 } keep state
+block drop quick all
+RULES
+
+if ! pfctl -a "$ANCHOR" -f "$RULES_FILE"; then
+    echo "Could not load the isolation rules."
+    exit 1
+fi
+pfctl -E
+
+echo "The host is isolated from the network, only the Fleet server $FLEET_SERVER_HOST is reachable."
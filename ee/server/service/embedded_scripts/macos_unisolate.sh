@@ -0,0 +1,11 @@
+#!/bin/sh
+
+# Release the host from the network isolation applied by Fleet.
+
+ANCHOR="com.apple/999.FleetIsolation"
+RULES_FILE="/etc/pf.anchors/com.fleetdm.isolation"
+
+pfctl -a "$ANCHOR" -F all
+rm -f "$RULES_FILE"
+
+echo "The host is no longer isolated from the network."
@@ -0,0 +1,32 @@
+# PowerShell script to isolate the host from the network, only allowing the
+# traffic with the Fleet server (and DNS to resolve it) so that the host can
+# still be managed and released from isolation.
+
+$fleetServerHost = "{{ .FleetServerHost }}"
+$ruleGroup = "Fleet isolation"
+$stateDir = Join-Path $env:ProgramData "FleetIsolation"
+$stateFile = Join-Path $stateDir "firewall_profiles.xml"
+
+try {
+    $fleetAddresses = [System.Net.Dns]::GetHostAddresses($fleetServerHost) | ForEach-Object { $_.IPAddressToString }
+} catch {
+    Write-Host "Could not resolve the Fleet server $fleetServerHost, not isolating the host."
+    exit 1
+}
+
+# Save the current default actions of the firewall profiles to restore them
+# when the host is released from isolation (unless already isolated).
+if (-not (Test-Path $stateFile)) {
+    New-Item -ItemType Directory -Force -Path $stateDir | Out-Null
+    Get-NetFirewallProfile | Select-Object Name, Enabled, DefaultInboundAction, DefaultOutboundAction | Export-Clixml -Path $stateFile
+}
+
+Get-NetFirewallRule -Group $ruleGroup -ErrorAction SilentlyContinue | Remove-NetFirewallRule
+New-NetFirewallRule -DisplayName "Fleet isolation - Fleet server" -Group $ruleGroup -Direction Outbound -Action Allow -RemoteAddress $fleetAddresses | Out-Null
+New-NetFirewallRule -DisplayName "Fleet isolation - DNS (UDP)" -Group $ruleGroup -Direction Outbound -Action Allow -Protocol UDP -RemotePort 53 | Out-Null
+New-NetFirewallRule -DisplayName "Fleet isolation - DNS (TCP)" -Group $ruleGroup -Direction Outbound -Action Allow -Protocol TCP -RemotePort 53 | Out-Null
+
+# Block everything else
+Set-NetFirewallProfile -All -Enabled True -DefaultInboundAction Block -DefaultOutboundAction Block -AllowInboundRules False
+
+Write-Host "The host is isolated from the network, only the Fleet server $fleetServerHost is reachable."
@@ -0,0 +1,19 @@
+# PowerShell script to release the host from the network isolation applied by
+# Fleet.
+
+$ruleGroup = "Fleet isolation"
+$stateDir = Join-Path $env:ProgramData "FleetIsolation"
+$stateFile = Join-Path $stateDir "firewall_profiles.xml"
+
+if (Test-Path $stateFile) {
+    Import-Clixml -Path $stateFile | ForEach-Object {
+        Set-NetFirewallProfile -Name $_.Name -Enabled $_.Enabled -DefaultInboundAction $_.DefaultInboundAction -DefaultOutboundAction $_.DefaultOutboundAction -AllowInboundRules NotConfigured
+    }
+    Remove-Item -Recurse -Force -Path $stateDir
+} else {
+    Set-NetFirewallProfile -All -DefaultInboundAction NotConfigured -DefaultOutboundAction NotConfigured -AllowInboundRules NotConfigured
+}
+
+Get-NetFirewallRule -Group $ruleGroup -ErrorAction SilentlyContinue | Remove-NetFirewallRule
+
+Write-Host "The host is no longer isolated from the network."
@@ -1,16 +1,23 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
+	"github.com/go-kit/kit/log/level"
 	"github.com/google/uuid"
 )
 
@@ -38,22 +45,22 @@ func (svc *Service) OSVersion(ctx context.Context, osID uint, teamID *uint, incl
 	return svc.Service.OSVersion(ctx, osID, teamID, true)
 }
 
-func (svc *Service) LockHost(ctx context.Context, hostID uint) error {
+func (svc *Service) LockHost(ctx context.Context, hostID uint, confirmation string) (bool, error) {
 	// First ensure the user has access to list hosts, then check the specific
 	// host once team_id is loaded.
 	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
-		return err
+		return false, err
 	}
 	host, err := svc.ds.HostLite(ctx, hostID)
 	if err != nil {
-		return ctxerr.Wrap(ctx, err, "get host lite")
+		return false, ctxerr.Wrap(ctx, err, "get host lite")
 	}
 
 	// Authorize again with team loaded now that we have the host's team_id.
 	// Authorize as "execute mdm_command", which is the correct access
 	// requirement and is what happens for macOS platforms.
 	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
-		return err
+		return false, err
 	}
 
 	// locking validations are based on the platform of the host
@@ -63,19 +70,25 @@ func (svc *Service) LockHost(ctx context.Context, hostID uint) error {
 			if errors.Is(err, fleet.ErrMDMNotConfigured) {
 				err = fleet.NewInvalidArgumentError("host_id", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
 			}
-			return ctxerr.Wrap(ctx, err, "check macOS MDM enabled")
+			return false, ctxerr.Wrap(ctx, err, "check macOS MDM enabled")
 		}
 
 		// on macOS, the lock command requires the host to be MDM-enrolled in Fleet
 		hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
 		if err != nil {
 			if fleet.IsNotFound(err) {
-				return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock the host because it doesn't have MDM turned on."))
+				return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock the host because it doesn't have MDM turned on."))
 			}
-			return ctxerr.Wrap(ctx, err, "get host MDM information")
+			return false, ctxerr.Wrap(ctx, err, "get host MDM information")
 		}
 		if !hostMDM.IsFleetEnrolled() {
-			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock the host because it doesn't have MDM turned on."))
+			return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock the host because it doesn't have MDM turned on."))
+		}
+
+		// the lock command sets a PIN, which Apple doesn't allow on personally-owned
+		// hosts enrolled via User Enrollment.
+		if err := svc.checkAppleUserEnrollment(ctx, host, "lock", fleet.MDMAppleUserEnrollmentLockPINMessage); err != nil {
+			return false, err
 		}
 
 	case "windows", "linux":
@@ -84,27 +97,27 @@ func (svc *Service) LockHost(ctx context.Context, hostID uint) error {
 				if errors.Is(err, fleet.ErrMDMNotConfigured) {
 					err = fleet.NewInvalidArgumentError("host_id", fleet.WindowsMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
 				}
-				return ctxerr.Wrap(ctx, err, "check windows MDM enabled")
+				return false, ctxerr.Wrap(ctx, err, "check windows MDM enabled")
 			}
 		}
 		// on windows and linux, a script is used to lock the host so scripts must
 		// be enabled
 		appCfg, err := svc.ds.AppConfig(ctx)
 		if err != nil {
-			return ctxerr.Wrap(ctx, err, "get app config")
+			return false, ctxerr.Wrap(ctx, err, "get app config")
 		}
 		if appCfg.ServerSettings.ScriptsDisabled {
-			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock host because running scripts is disabled in organization settings."))
+			return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't lock host because running scripts is disabled in organization settings."))
 		}
 		hostOrbitInfo, err := svc.ds.GetHostOrbitInfo(ctx, host.ID)
 		switch {
 		case err != nil:
 			// If not found, then do nothing. We do not know if this host has scripts enabled or not
 			if !fleet.IsNotFound(err) {
-				return ctxerr.Wrap(ctx, err, "get host orbit info")
+				return false, ctxerr.Wrap(ctx, err, "get host orbit info")
 			}
 		case hostOrbitInfo.ScriptsEnabled != nil && !*hostOrbitInfo.ScriptsEnabled:
-			return ctxerr.Wrap(
+			return false, ctxerr.Wrap(
 				ctx, fleet.NewInvalidArgumentError(
 					"host_id", "Couldn't lock host. To lock, deploy the fleetd agent with --enable-scripts and refetch host vitals.",
 				),
@@ -112,30 +125,37 @@ func (svc *Service) LockHost(ctx context.Context, hostID uint) error {
 		}
 
 	default:
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unsupported host platform: %s", host.Platform)))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unsupported host platform: %s", host.Platform)))
 	}
 
 	// if there's a lock, unlock or wipe action pending, do not accept the lock
 	// request.
 	lockWipe, err := svc.ds.GetHostLockWipeStatus(ctx, host)
 	if err != nil {
-		return ctxerr.Wrap(ctx, err, "get host lock/wipe status")
+		return false, ctxerr.Wrap(ctx, err, "get host lock/wipe status")
 	}
 	switch {
 	case lockWipe.IsPendingLock():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending lock request. The host will lock when it comes online."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending lock request. The host will lock when it comes online."))
 	case lockWipe.IsPendingUnlock():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unlock request. Host cannot be locked again until unlock is complete."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unlock request. Host cannot be locked again until unlock is complete."))
 	case lockWipe.IsPendingWipe():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending wipe request. Cannot process lock requests once host is wiped."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending wipe request. Cannot process lock requests once host is wiped."))
 	case lockWipe.IsWiped():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is wiped. Cannot process lock requests once host is wiped."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is wiped. Cannot process lock requests once host is wiped."))
 	case lockWipe.IsLocked():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already locked.").WithStatus(http.StatusConflict))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already locked.").WithStatus(http.StatusConflict))
+	}
+
+	// the destructive action must be confirmed and, if required, approved by
+	// a second user before it is queued.
+	pendingApproval, err := svc.confirmHostLockWipe(ctx, host, fleet.HostLockWipeActionLock, confirmation)
+	if err != nil || pendingApproval {
+		return pendingApproval, err
 	}
 
 	// all good, go ahead with queuing the lock request.
-	return svc.enqueueLockHostRequest(ctx, host, lockWipe)
+	return false, svc.enqueueLockHostRequest(ctx, host, lockWipe)
 }
 
 func (svc *Service) UnlockHost(ctx context.Context, hostID uint) (string, error) {
@@ -226,22 +246,72 @@ func (svc *Service) UnlockHost(ctx context.Context, hostID uint) (string, error)
 	return svc.enqueueUnlockHostRequest(ctx, host, lockWipe)
 }
 
-func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
+func (svc *Service) GetHostUnlockPIN(ctx context.Context, hostID uint) (string, error) {
 	// First ensure the user has access to list hosts, then check the specific
 	// host once team_id is loaded.
 	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
-		return err
+		return "", err
 	}
 	host, err := svc.ds.HostLite(ctx, hostID)
 	if err != nil {
-		return ctxerr.Wrap(ctx, err, "get host lite")
+		return "", ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Reading the PIN requires the same access as unlocking the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return "", err
+	}
+
+	// only macOS hosts are unlocked with a PIN, windows and linux hosts are
+	// unlocked via a script.
+	if host.FleetPlatform() != "darwin" {
+		return "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unlock PIN is only available for macOS hosts, this host's platform is: %s", host.Platform)))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return "", fleet.ErrNoContext
+	}
+
+	lockWipe, err := svc.ds.GetHostLockWipeStatus(ctx, host)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get host lock/wipe status")
+	}
+	if (!lockWipe.IsPendingLock() && !lockWipe.IsLocked()) || lockWipe.UnlockPIN == "" {
+		return "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is not locked.").WithStatus(http.StatusConflict))
+	}
+
+	if err := svc.ds.NewActivity(
+		ctx,
+		vc.User,
+		fleet.ActivityTypeReadHostUnlockPIN{
+			HostID:          host.ID,
+			HostDisplayName: host.DisplayName(),
+		},
+	); err != nil {
+		return "", ctxerr.Wrap(ctx, err, "create activity for read host unlock PIN")
+	}
+
+	return lockWipe.UnlockPIN, nil
+}
+
+func (svc *Service) WipeHost(ctx context.Context, hostID uint, confirmation string) (bool, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return false, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "get host lite")
 	}
 
 	// Authorize again with team loaded now that we have the host's team_id.
 	// Authorize as "execute mdm_command", which is the correct access
 	// requirement and is what happens for macOS platforms.
 	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
-		return err
+		return false, err
 	}
 
 	// wipe validations are based on the platform of the host, Windows and macOS
@@ -254,7 +324,7 @@ func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
 			if errors.Is(err, fleet.ErrMDMNotConfigured) {
 				err = fleet.NewInvalidArgumentError("host_id", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
 			}
-			return ctxerr.Wrap(ctx, err, "check macOS MDM enabled")
+			return false, ctxerr.Wrap(ctx, err, "check macOS MDM enabled")
 		}
 		requireMDM = true
 
@@ -263,7 +333,7 @@ func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
 			if errors.Is(err, fleet.ErrMDMNotConfigured) {
 				err = fleet.NewInvalidArgumentError("host_id", fleet.WindowsMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
 			}
-			return ctxerr.Wrap(ctx, err, "check windows MDM enabled")
+			return false, ctxerr.Wrap(ctx, err, "check windows MDM enabled")
 		}
 		requireMDM = true
 
@@ -271,20 +341,20 @@ func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
 		// on linux, a script is used to wipe the host so scripts must be enabled
 		appCfg, err := svc.ds.AppConfig(ctx)
 		if err != nil {
-			return ctxerr.Wrap(ctx, err, "get app config")
+			return false, ctxerr.Wrap(ctx, err, "get app config")
 		}
 		if appCfg.ServerSettings.ScriptsDisabled {
-			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe host because running scripts is disabled in organization settings."))
+			return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe host because running scripts is disabled in organization settings."))
 		}
 		hostOrbitInfo, err := svc.ds.GetHostOrbitInfo(ctx, host.ID)
 		switch {
 		case err != nil:
 			// If not found, then do nothing. We do not know if this host has scripts enabled or not
 			if !fleet.IsNotFound(err) {
-				return ctxerr.Wrap(ctx, err, "get host orbit info")
+				return false, ctxerr.Wrap(ctx, err, "get host orbit info")
 			}
 		case hostOrbitInfo.ScriptsEnabled != nil && !*hostOrbitInfo.ScriptsEnabled:
-			return ctxerr.Wrap(
+			return false, ctxerr.Wrap(
 				ctx, fleet.NewInvalidArgumentError(
 					"host_id", "Couldn't wipe host. To wipe, deploy the fleetd agent with --enable-scripts and refetch host vitals.",
 				),
@@ -292,7 +362,7 @@ func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
 		}
 
 	default:
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unsupported host platform: %s", host.Platform)))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unsupported host platform: %s", host.Platform)))
 	}
 
 	if requireMDM {
@@ -300,35 +370,155 @@ func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
 		hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
 		if err != nil {
 			if fleet.IsNotFound(err) {
-				return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe the host because it doesn't have MDM turned on."))
+				return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe the host because it doesn't have MDM turned on."))
 			}
-			return ctxerr.Wrap(ctx, err, "get host MDM information")
+			return false, ctxerr.Wrap(ctx, err, "get host MDM information")
 		}
 		if !hostMDM.IsFleetEnrolled() {
-			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe the host because it doesn't have MDM turned on."))
+			return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't wipe the host because it doesn't have MDM turned on."))
+		}
+
+		if host.FleetPlatform() == "darwin" {
+			if err := svc.checkAppleUserEnrollment(ctx, host, "wipe", fleet.MDMAppleUserEnrollmentEraseMessage); err != nil {
+				return false, err
+			}
 		}
 	}
 
 	// validations based on host's actions status (pending lock, unlock, wipe)
 	lockWipe, err := svc.ds.GetHostLockWipeStatus(ctx, host)
 	if err != nil {
-		return ctxerr.Wrap(ctx, err, "get host lock/wipe status")
+		return false, ctxerr.Wrap(ctx, err, "get host lock/wipe status")
 	}
 	switch {
 	case lockWipe.IsPendingLock():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending lock request. Host cannot be wiped until lock is complete."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending lock request. Host cannot be wiped until lock is complete."))
 	case lockWipe.IsPendingUnlock():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unlock request. Host cannot be wiped until unlock is complete."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unlock request. Host cannot be wiped until unlock is complete."))
 	case lockWipe.IsPendingWipe():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending wipe request. The host will be wiped when it comes online."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending wipe request. The host will be wiped when it comes online."))
 	case lockWipe.IsLocked():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is locked. Host cannot be wiped until it is unlocked."))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is locked. Host cannot be wiped until it is unlocked."))
 	case lockWipe.IsWiped():
-		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already wiped.").WithStatus(http.StatusConflict))
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already wiped.").WithStatus(http.StatusConflict))
+	}
+
+	// the destructive action must be confirmed and, if required, approved by
+	// a second user before it is queued.
+	pendingApproval, err := svc.confirmHostLockWipe(ctx, host, fleet.HostLockWipeActionWipe, confirmation)
+	if err != nil || pendingApproval {
+		return pendingApproval, err
 	}
 
 	// all good, go ahead with queuing the wipe request.
-	return svc.enqueueWipeHostRequest(ctx, host, lockWipe)
+	return false, svc.enqueueWipeHostRequest(ctx, host, lockWipe)
+}
+
+// confirmHostLockWipe validates the confirmation of a lock or wipe request
+// and, if the host's team (or no team) requires a second approver, records the
+// request or approves the request made by another user. It returns true if
+// the request is waiting for approval, in which case the action must not be
+// queued yet.
+func (svc *Service) confirmHostLockWipe(ctx context.Context, host *fleet.Host, action, confirmation string) (bool, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return false, fleet.ErrNoContext
+	}
+
+	if !host.IsValidLockWipeConfirmation(confirmation) {
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("confirmation",
+			fmt.Sprintf("To %s the host, confirm with the host's serial number or the confirmation token: %s", action, host.LockWipeConfirmationToken())))
+	}
+
+	settings, err := svc.hostLockWipeSettings(ctx, host)
+	if err != nil {
+		return false, err
+	}
+	if !settings.RequireSecondApprover {
+		return false, nil
+	}
+
+	req, err := svc.ds.GetHostLockWipeApprovalRequest(ctx, host.ID, action)
+	if err != nil && !fleet.IsNotFound(err) {
+		return false, ctxerr.Wrap(ctx, err, "get host lock/wipe approval request")
+	}
+	if req == nil || req.IsExpired(svc.clock.Now()) {
+		// first request, it must be approved by another user
+		if err := svc.ds.NewHostLockWipeApprovalRequest(ctx, host.ID, action, vc.User.ID); err != nil {
+			return false, ctxerr.Wrap(ctx, err, "create host lock/wipe approval request")
+		}
+		if err := svc.ds.NewActivity(
+			ctx,
+			vc.User,
+			fleet.ActivityTypeRequestedHostLockWipeApproval{
+				HostID:          host.ID,
+				HostDisplayName: host.DisplayName(),
+				Action:          action,
+			},
+		); err != nil {
+			return false, ctxerr.Wrap(ctx, err, "create activity for host lock/wipe approval request")
+		}
+		return true, nil
+	}
+
+	if req.RequestedByUserID != nil && *req.RequestedByUserID == vc.User.ID {
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Host has pending %s request waiting for approval. The request must be approved by a different user.", action)).WithStatus(http.StatusConflict))
+	}
+
+	// approved by a second user, the action can be queued by the approver that
+	// claims the request, if it was approved concurrently by another user it
+	// is already queued.
+	claimed, err := svc.ds.ClaimHostLockWipeApprovalRequest(ctx, req)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "claim host lock/wipe approval request")
+	}
+	if !claimed {
+		return false, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Host %s request was already approved by another user.", action)).WithStatus(http.StatusConflict))
+	}
+	return false, nil
+}
+
+// hostLockWipeSettings returns the lock and wipe settings of the host's team,
+// or the global settings if the host has no team.
+func (svc *Service) hostLockWipeSettings(ctx context.Context, host *fleet.Host) (fleet.LockWipeSettings, error) {
+	if host.TeamID != nil {
+		tm, err := svc.ds.Team(ctx, *host.TeamID)
+		if err != nil {
+			return fleet.LockWipeSettings{}, ctxerr.Wrap(ctx, err, "get team")
+		}
+		return tm.Config.LockWipeSettings, nil
+	}
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return fleet.LockWipeSettings{}, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	return appCfg.LockWipeSettings, nil
+}
+
+// notifyLockWipeEndUser sends the lock and wipe notification email to the
+// end user of the host. This is best-effort, failing to send the email does
+// not fail the request.
+func (svc *Service) notifyLockWipeEndUser(ctx context.Context, host *fleet.Host, action string) {
+	if err := svc.sendLockWipeNotificationEmail(ctx, host, action); err != nil {
+		level.Error(svc.logger).Log("msg", "failed to send lock/wipe notification email", "host_id", host.ID, "action", action, "err", err)
+	}
+}
+
+// checkAppleUserEnrollment returns an error if the Apple host is enrolled via
+// User Enrollment (BYOD), on which Apple doesn't allow the action for privacy
+// reasons.
+func (svc *Service) checkAppleUserEnrollment(ctx context.Context, host *fleet.Host, action, restriction string) error {
+	enrollment, err := svc.ds.GetNanoMDMEnrollment(ctx, host.UUID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get nano MDM enrollment")
+	}
+	if enrollment != nil && enrollment.IsUserEnrollment() {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Can't %s the host because it's personally-owned and enrolled via User Enrollment (BYOD). %s", action, restriction)))
+	}
+	return nil
 }
 
 func (svc *Service) enqueueLockHostRequest(ctx context.Context, host *fleet.Host, lockStatus *fleet.HostLockWipeStatus) error {
@@ -354,6 +544,7 @@ func (svc *Service) enqueueLockHostRequest(ctx context.Context, host *fleet.Host
 			return ctxerr.Wrap(ctx, err, "create activity for darwin lock host request")
 		}
 
+		svc.notifyLockWipeEndUser(ctx, host, mail.LockWipeActionLocked)
 		return nil
 	}
 
@@ -388,6 +579,7 @@ func (svc *Service) enqueueLockHostRequest(ctx context.Context, host *fleet.Host
 		return ctxerr.Wrap(ctx, err, "create activity for lock host request")
 	}
 
+	svc.notifyLockWipeEndUser(ctx, host, mail.LockWipeActionLocked)
 	return nil
 }
 
@@ -397,8 +589,12 @@ func (svc *Service) enqueueUnlockHostRequest(ctx context.Context, host *fleet.Ho
 		return "", fleet.ErrNoContext
 	}
 
+	// on macOS, the unlock request is repeated to get the PIN again, the end
+	// user is only notified of the first one.
+	notify := true
 	var unlockPIN string
 	if lockStatus.HostFleetPlatform == "darwin" {
+		notify = lockStatus.UnlockRequestedAt.IsZero()
 		// record the unlock request if it was not already recorded
 		if lockStatus.UnlockRequestedAt.IsZero() {
 			if err := svc.ds.UnlockHostManually(ctx, host.ID, host.FleetPlatform(), time.Now().UTC()); err != nil {
@@ -439,6 +635,9 @@ func (svc *Service) enqueueUnlockHostRequest(ctx context.Context, host *fleet.Ho
 		return "", ctxerr.Wrap(ctx, err, "create activity for unlock host request")
 	}
 
+	if notify {
+		svc.notifyLockWipeEndUser(ctx, host, mail.LockWipeActionUnlocked)
+	}
 	return unlockPIN, nil
 }
 
@@ -450,8 +649,12 @@ func (svc *Service) enqueueWipeHostRequest(ctx context.Context, host *fleet.Host
 
 	switch wipeStatus.HostFleetPlatform {
 	case "darwin":
+		opts, err := svc.mdmAppleEraseDeviceOptions(ctx, host.TeamID)
+		if err != nil {
+			return err
+		}
 		wipeCommandUUID := uuid.NewString()
-		if err := svc.mdmAppleCommander.EraseDevice(ctx, host, wipeCommandUUID); err != nil {
+		if err := svc.mdmAppleCommander.EraseDevice(ctx, host, wipeCommandUUID, opts); err != nil {
 			return ctxerr.Wrap(ctx, err, "enqueuing wipe request for darwin")
 		}
 
@@ -492,6 +695,176 @@ func (svc *Service) enqueueWipeHostRequest(ctx context.Context, host *fleet.Host
 	); err != nil {
 		return ctxerr.Wrap(ctx, err, "create activity for wipe host request")
 	}
+
+	svc.notifyLockWipeEndUser(ctx, host, mail.LockWipeActionWiped)
+	return nil
+}
+
+func (svc *Service) IsolateHost(ctx context.Context, hostID uint) error {
+	host, err := svc.validateHostIsolationRequest(ctx, hostID, "isolate")
+	if err != nil {
+		return err
+	}
+
+	isolation, err := svc.ds.GetHostLockWipeStatus(ctx, host)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host isolation status")
+	}
+	switch {
+	case isolation.IsPendingWipe():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending wipe request. Cannot process isolate requests once host is wiped."))
+	case isolation.IsWiped():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is wiped. Cannot process isolate requests once host is wiped."))
+	case isolation.IsPendingIsolate():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending isolate request. The host will be isolated when it comes online."))
+	case isolation.IsPendingUnisolate():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unisolate request. Host cannot be isolated again until unisolate is complete."))
+	case isolation.IsIsolated():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already isolated.").WithStatus(http.StatusConflict))
+	}
+
+	// all good, go ahead with queuing the isolate request.
+	return svc.enqueueHostIsolationRequest(ctx, host, isolation.HostFleetPlatform, true)
+}
+
+func (svc *Service) UnisolateHost(ctx context.Context, hostID uint) error {
+	host, err := svc.validateHostIsolationRequest(ctx, hostID, "unisolate")
+	if err != nil {
+		return err
+	}
+
+	isolation, err := svc.ds.GetHostLockWipeStatus(ctx, host)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host isolation status")
+	}
+	switch {
+	case isolation.IsPendingIsolate():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending isolate request. Host cannot be unisolated until isolate is complete."))
+	case isolation.IsPendingUnisolate():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending unisolate request. The host will be unisolated when it comes online."))
+	case !isolation.IsIsolated():
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is not isolated.").WithStatus(http.StatusConflict))
+	}
+
+	// all good, go ahead with queuing the unisolate request.
+	return svc.enqueueHostIsolationRequest(ctx, host, isolation.HostFleetPlatform, false)
+}
+
+// validateHostIsolationRequest authorizes the isolate or unisolate action on
+// the host and validates that the host can run the isolation scripts.
+func (svc *Service) validateHostIsolationRequest(ctx context.Context, hostID uint, action string) (*fleet.Host, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Isolation requires the same access as locking the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	switch host.FleetPlatform() {
+	case "darwin", "windows", "linux":
+	default:
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Unsupported host platform: %s", host.Platform)))
+	}
+
+	// on all platforms, a script is used to apply the firewall rules so scripts
+	// must be enabled
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if appCfg.ServerSettings.ScriptsDisabled {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Can't %s host because running scripts is disabled in organization settings.", action)))
+	}
+	hostOrbitInfo, err := svc.ds.GetHostOrbitInfo(ctx, host.ID)
+	switch {
+	case err != nil:
+		// If not found, then do nothing. We do not know if this host has scripts enabled or not
+		if !fleet.IsNotFound(err) {
+			return nil, ctxerr.Wrap(ctx, err, "get host orbit info")
+		}
+	case hostOrbitInfo.ScriptsEnabled != nil && !*hostOrbitInfo.ScriptsEnabled:
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Couldn't %[1]s host. To %[1]s, deploy the fleetd agent with --enable-scripts and refetch host vitals.", action)))
+	}
+	return host, nil
+}
+
+func (svc *Service) enqueueHostIsolationRequest(ctx context.Context, host *fleet.Host, hostFleetPlatform string, isolate bool) error {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	serverURL, err := url.Parse(appCfg.ServerSettings.ServerURL)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "parse server url")
+	}
+
+	var script bytes.Buffer
+	tmpl := isolationScripts[hostFleetPlatform].unisolate
+	if isolate {
+		tmpl = isolationScripts[hostFleetPlatform].isolate
+	}
+	if err := tmpl.Execute(&script, struct{ FleetServerHost string }{serverURL.Hostname()}); err != nil {
+		return ctxerr.Wrap(ctx, err, "execute isolation script template")
+	}
+
+	// macOS hosts enrolled in Fleet's MDM also receive a profile restricting
+	// their network features while they are isolated.
+	if hostFleetPlatform == "darwin" && appCfg.MDM.EnabledAndConfigured {
+		hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+		if err != nil && !fleet.IsNotFound(err) {
+			return ctxerr.Wrap(ctx, err, "get host MDM information")
+		}
+		if hostMDM != nil && hostMDM.IsFleetEnrolled() {
+			if isolate {
+				err = svc.mdmAppleCommander.InstallProfile(ctx, []string{host.UUID}, mobileconfig.FleetIsolationProfile, uuid.NewString())
+			} else {
+				err = svc.mdmAppleCommander.RemoveProfile(ctx, []string{host.UUID}, mobileconfig.FleetIsolationPayloadIdentifier, uuid.NewString())
+			}
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "enqueuing isolation profile command for darwin")
+			}
+		}
+	}
+
+	// as for lock and wipe, the script is enqueued directly in the datastore
+	// layer, bypassing the validations of svc.RunHostScript.
+	request := &fleet.HostScriptRequestPayload{
+		HostID:         host.ID,
+		ScriptContents: script.String(),
+		UserID:         &vc.User.ID,
+		SyncRequest:    false,
+	}
+	var activity fleet.ActivityDetails
+	if isolate {
+		err = svc.ds.IsolateHostViaScript(ctx, request, hostFleetPlatform)
+		activity = fleet.ActivityTypeIsolatedHost{HostID: host.ID, HostDisplayName: host.DisplayName()}
+	} else {
+		err = svc.ds.UnisolateHostViaScript(ctx, request, hostFleetPlatform)
+		activity = fleet.ActivityTypeUnisolatedHost{HostID: host.ID, HostDisplayName: host.DisplayName()}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, activity); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for host isolation request")
+	}
 	return nil
 }
 
@@ -512,6 +885,37 @@ var (
 	linuxUnlockScript []byte
 	//go:embed embedded_scripts/linux_wipe.sh
 	linuxWipeScript []byte
+	//go:embed embedded_scripts/linux_isolate.sh
+	linuxIsolateScript string
+	//go:embed embedded_scripts/linux_unisolate.sh
+	linuxUnisolateScript string
+	//go:embed embedded_scripts/macos_isolate.sh
+	macosIsolateScript string
+	//go:embed embedded_scripts/macos_unisolate.sh
+	macosUnisolateScript string
+	//go:embed embedded_scripts/windows_isolate.ps1
+	windowsIsolateScript string
+	//go:embed embedded_scripts/windows_unisolate.ps1
+	windowsUnisolateScript string
+
+	// isolationScripts are the templates of the scripts that isolate the host
+	// from the network and release it, by fleet platform. They are executed
+	// with the hostname of the Fleet server, which the hosts can still reach
+	// while isolated.
+	isolationScripts = map[string]struct{ isolate, unisolate *template.Template }{
+		"darwin": {
+			isolate:   template.Must(template.New("").Parse(macosIsolateScript)),
+			unisolate: template.Must(template.New("").Parse(macosUnisolateScript)),
+		},
+		"windows": {
+			isolate:   template.Must(template.New("").Parse(windowsIsolateScript)),
+			unisolate: template.Must(template.New("").Parse(windowsUnisolateScript)),
+		},
+		"linux": {
+			isolate:   template.Must(template.New("").Parse(linuxIsolateScript)),
+			unisolate: template.Must(template.New("").Parse(linuxUnisolateScript)),
+		},
+	}
 
 	windowsWipeCommand = `
 		<Exec>
@@ -528,3 +932,452 @@ var (
 			</Item>
 		</Exec>`
 )
+
+func (svc *Service) EnableHostLostMode(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings) error {
+	host, err := svc.validateHostLostModeRequest(ctx, hostID, "enable Lost Mode on")
+	if err != nil {
+		return err
+	}
+	if err := settings.Validate(); err != nil {
+		return ctxerr.Wrap(ctx, err, "validate Lost Mode settings")
+	}
+
+	lostMode, err := svc.ds.GetHostLostMode(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host Lost Mode")
+	}
+	if lostMode != nil {
+		switch lostMode.Status {
+		case fleet.HostLostModeStatusPendingEnable:
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending Lost Mode request. The host will enter Lost Mode when it comes online."))
+		case fleet.HostLostModeStatusPendingDisable:
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending request to disable Lost Mode. Lost Mode cannot be enabled again until it is disabled."))
+		case fleet.HostLostModeStatusEnabled:
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is already in Lost Mode.").WithStatus(http.StatusConflict))
+		}
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	cmdUUID := uuid.NewString()
+	if err := svc.mdmAppleCommander.EnableLostMode(ctx, []string{host.UUID}, cmdUUID, settings); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing enable Lost Mode command")
+	}
+	if err := svc.ds.NewHostLostMode(ctx, host.ID, settings, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record host Lost Mode")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeEnabledLostMode{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for enable Lost Mode request")
+	}
+	return nil
+}
+
+func (svc *Service) DisableHostLostMode(ctx context.Context, hostID uint) error {
+	host, err := svc.validateHostLostModeRequest(ctx, hostID, "disable Lost Mode on")
+	if err != nil {
+		return err
+	}
+
+	lostMode, err := svc.ds.GetHostLostMode(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host Lost Mode")
+	}
+	switch {
+	case lostMode == nil:
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host is not in Lost Mode.").WithStatus(http.StatusConflict))
+	case lostMode.Status == fleet.HostLostModeStatusPendingEnable:
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending Lost Mode request. Lost Mode cannot be disabled until the host enters Lost Mode."))
+	case lostMode.Status == fleet.HostLostModeStatusPendingDisable:
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending request to disable Lost Mode. The host will exit Lost Mode when it comes online."))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	cmdUUID := uuid.NewString()
+	if err := svc.mdmAppleCommander.DisableLostMode(ctx, []string{host.UUID}, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing disable Lost Mode command")
+	}
+	if err := svc.ds.SetHostLostModePendingDisable(ctx, host.ID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record host Lost Mode disable request")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeDisabledLostMode{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for disable Lost Mode request")
+	}
+	return nil
+}
+
+func (svc *Service) GetHostLostMode(ctx context.Context, hostID uint) (*fleet.HostLostMode, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	lostMode, err := svc.ds.GetHostLostMode(ctx, host.ID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return &fleet.HostLostMode{HostID: host.ID, Status: fleet.HostLostModeStatusDisabled}, nil
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host Lost Mode")
+	}
+	return lostMode, nil
+}
+
+func (svc *Service) RequestHostLocation(ctx context.Context, hostID uint) error {
+	host, err := svc.validateHostLostModeRequest(ctx, hostID, "locate")
+	if err != nil {
+		return err
+	}
+
+	// the host responds with an error to the DeviceLocation command if it is
+	// not in Lost Mode.
+	lostMode, err := svc.ds.GetHostLostMode(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host Lost Mode")
+	}
+	if lostMode == nil || lostMode.Status != fleet.HostLostModeStatusEnabled {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't locate the host because it's not in Lost Mode.").WithStatus(http.StatusConflict))
+	}
+
+	if err := svc.mdmAppleCommander.DeviceLocation(ctx, []string{host.UUID}, uuid.NewString()); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing device location command")
+	}
+	return nil
+}
+
+func (svc *Service) GetHostLastLocation(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Reading the location requires the same access as requesting it.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	loc, err := svc.ds.GetHostLastLocation(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host last location")
+	}
+	return loc, nil
+}
+
+// validateHostLostModeRequest authorizes the Lost Mode commands on the host
+// and validates that the host is an iOS or iPadOS host enrolled in Fleet's
+// MDM.
+func (svc *Service) validateHostLostModeRequest(ctx context.Context, hostID uint, action string) (*fleet.Host, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Lost Mode requires the same access as locking the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if host.Platform != "ios" && host.Platform != "ipados" {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Can't %s the host because Lost Mode is only available for iOS and iPadOS hosts, this host's platform is: %s", action, host.Platform)))
+	}
+
+	if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+		if errors.Is(err, fleet.ErrMDMNotConfigured) {
+			err = fleet.NewInvalidArgumentError("host_id", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "check Apple MDM enabled")
+	}
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return nil, ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	if hostMDM == nil || !hostMDM.IsFleetEnrolled() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Can't %s the host because it doesn't have MDM turned on.", action)))
+	}
+	return host, nil
+}
+
+func (svc *Service) GetHostActivationLockBypassCode(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Reading the bypass code requires the same access as wiping the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	code, err := svc.ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host activation lock bypass code")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeReadHostActivationLockBypassCode{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for read host activation lock bypass code")
+	}
+	return code, nil
+}
+
+func (svc *Service) ClearHostActivationLockBypassCode(ctx context.Context, hostID uint) error {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if !fleet.IsApplePlatform(host.Platform) {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", fmt.Sprintf("Activation Lock is only available for Apple hosts, this host's platform is: %s", host.Platform)))
+	}
+	if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+		if errors.Is(err, fleet.ErrMDMNotConfigured) {
+			err = fleet.NewInvalidArgumentError("host_id", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
+		}
+		return ctxerr.Wrap(ctx, err, "check Apple MDM enabled")
+	}
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	if hostMDM == nil || !hostMDM.IsFleetEnrolled() {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't clear the Activation Lock bypass code because the host doesn't have MDM turned on."))
+	}
+
+	code, err := svc.ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has no escrowed Activation Lock bypass code.").WithStatus(http.StatusConflict))
+		}
+		return ctxerr.Wrap(ctx, err, "get host activation lock bypass code")
+	}
+	if code.ClearCommandUUID != nil {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending request to clear the Activation Lock bypass code. The code will be cleared when the host comes online."))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	cmdUUID := uuid.NewString()
+	if err := svc.mdmAppleCommander.ClearActivationLockBypassCode(ctx, []string{host.UUID}, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing clear activation lock bypass code command")
+	}
+	if err := svc.ds.SetHostMDMAppleActivationLockBypassCodePendingClear(ctx, host.ID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record clear activation lock bypass code request")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeClearedHostActivationLockBypassCode{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for clear activation lock bypass code request")
+	}
+	return nil
+}
+
+func (svc *Service) GetHostRecoveryLockPassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Reading the password requires the same access as locking the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	pwd, err := svc.ds.GetHostMDMAppleRecoveryLockPassword(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host recovery lock password")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeReadHostRecoveryLockPassword{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for read host recovery lock password")
+	}
+	return pwd, nil
+}
+
+func (svc *Service) SetHostFirmwarePassword(ctx context.Context, hostID uint) error {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+	// the full host is loaded for its CPU type.
+	host, err := svc.ds.Host(ctx, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Setting the password requires the same access as locking the host.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	// Apple silicon Macs have no firmware password, they are protected by
+	// the Recovery Lock password instead.
+	if host.FleetPlatform() != "darwin" || strings.HasPrefix(host.CPUType, "arm64") {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Firmware password is only available for Intel Macs."))
+	}
+	if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+		if errors.Is(err, fleet.ErrMDMNotConfigured) {
+			err = fleet.NewInvalidArgumentError("host_id", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest)
+		}
+		return ctxerr.Wrap(ctx, err, "check Apple MDM enabled")
+	}
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	if hostMDM == nil || !hostMDM.IsFleetEnrolled() {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't set the firmware password because the host doesn't have MDM turned on."))
+	}
+	if err := svc.checkAppleUserEnrollment(ctx, host, "set the firmware password of", "Firmware password is only available for company-owned Macs."); err != nil {
+		return err
+	}
+	// the password is escrowed encrypted with the server private key.
+	if svc.config.Server.PrivateKey == "" {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Can't set the firmware password because the Fleet server private key is not configured."))
+	}
+
+	var currentPassword string
+	pwd, err := svc.ds.GetHostMDMAppleFirmwarePassword(ctx, host.ID)
+	switch {
+	case fleet.IsNotFound(err):
+		// first password set by Fleet
+	case err != nil:
+		return ctxerr.Wrap(ctx, err, "get host firmware password")
+	case pwd.SetCommandUUID != nil:
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has pending firmware password request. The password will be set when the host comes online."))
+	default:
+		currentPassword = pwd.Password
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	if err := svc.mdmAppleCommander.SetFirmwarePassword(ctx, host, uuid.NewString(), currentPassword); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing set firmware password command")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeSetHostFirmwarePassword{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for set host firmware password")
+	}
+	return nil
+}
+
+func (svc *Service) GetHostFirmwarePassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	// Reading the password requires the same access as setting it.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	pwd, err := svc.ds.GetHostMDMAppleFirmwarePassword(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host firmware password")
+	}
+	if pwd.Password == "" {
+		// the first SetFirmwarePassword command is pending
+		return nil, ctxerr.Wrap(ctx, notFoundError{}, "host firmware password is pending")
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeReadHostFirmwarePassword{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for read host firmware password")
+	}
+	return pwd, nil
+}
@@ -139,13 +139,43 @@ func (svc *Service) MDMAppleEraseDevice(ctx context.Context, hostID uint) error
 		return err
 	}
 
-	err = svc.mdmAppleCommander.EraseDevice(ctx, host, uuid.New().String())
+	opts, err := svc.mdmAppleEraseDeviceOptions(ctx, host.TeamID)
+	if err != nil {
+		return err
+	}
+	err = svc.mdmAppleCommander.EraseDevice(ctx, host, uuid.New().String(), opts)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// mdmAppleEraseDeviceOptions returns the options of the EraseDevice command
+// configured for the team, or for "No team" if teamID is nil.
+func (svc *Service) mdmAppleEraseDeviceOptions(ctx context.Context, teamID *uint) (fleet.MDMAppleEraseDeviceOptions, error) {
+	var settings fleet.MacOSSettings
+	if teamID != nil {
+		tmMDM, err := svc.ds.TeamMDMConfig(ctx, *teamID)
+		if err != nil {
+			return fleet.MDMAppleEraseDeviceOptions{}, ctxerr.Wrap(ctx, err, "get team MDM config")
+		}
+		if tmMDM != nil {
+			settings = tmMDM.MacOSSettings
+		}
+	} else {
+		appCfg, err := svc.ds.AppConfig(ctx)
+		if err != nil {
+			return fleet.MDMAppleEraseDeviceOptions{}, ctxerr.Wrap(ctx, err, "get app config")
+		}
+		settings = appCfg.MDM.MacOSSettings
+	}
+
+	if settings.EraseDevice == nil {
+		return fleet.MDMAppleEraseDeviceOptions{}, nil
+	}
+	return *settings.EraseDevice, nil
+}
+
 func (svc *Service) MDMListHostConfigurationProfiles(ctx context.Context, hostID uint) ([]*fleet.MDMAppleConfigProfile, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionSelectiveList); err != nil {
 		return nil, err
@@ -1027,30 +1057,43 @@ func (svc *Service) GetMDMDiskEncryptionSummary(ctx context.Context, teamID *uin
 		windows = *w
 	}
 
+	var linux fleet.MDMLinuxDiskEncryptionSummary
+	if l, err := svc.ds.GetMDMLinuxDiskEncryptionSummary(ctx, teamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "getting linux disk encryption summary")
+	} else if l != nil {
+		linux = *l
+	}
+
 	return &fleet.MDMDiskEncryptionSummary{
 		Verified: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.Verified,
 			Windows: windows.Verified,
+			Linux:   linux.Verified,
 		},
 		Verifying: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.Verifying,
 			Windows: windows.Verifying,
+			Linux:   linux.Verifying,
 		},
 		ActionRequired: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.ActionRequired,
 			Windows: windows.ActionRequired,
+			Linux:   linux.ActionRequired,
 		},
 		Enforcing: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.Enforcing,
 			Windows: windows.Enforcing,
+			Linux:   linux.Enforcing,
 		},
 		Failed: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.Failed,
 			Windows: windows.Failed,
+			Linux:   linux.Failed,
 		},
 		RemovingEnforcement: fleet.MDMPlatformsCounts{
 			MacOS:   macOS.RemovingEnforcement,
 			Windows: windows.RemovingEnforcement,
+			Linux:   linux.RemovingEnforcement,
 		},
 	}, nil
 }
@@ -172,6 +172,9 @@ func TestGetOrCreatePreassignTeam(t *testing.T) {
 		ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 			return nil, errors.New("not implemented")
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 		ds.NewMDMAppleConfigProfileFunc = func(ctx context.Context, profile fleet.MDMAppleConfigProfile) (*fleet.MDMAppleConfigProfile, error) {
 			return nil, errors.New("not implemented")
 		}
@@ -45,6 +45,9 @@ func TestMDMAppleEnableFileVaultAndEscrow(t *testing.T) {
 		ds.NewMDMAppleConfigProfileFunc = func(ctx context.Context, p fleet.MDMAppleConfigProfile) (*fleet.MDMAppleConfigProfile, error) {
 			return nil, testErr
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 		err := svc.MDMAppleEnableFileVaultAndEscrow(ctx, nil)
 		require.ErrorIs(t, err, testErr)
 		require.True(t, ds.NewMDMAppleConfigProfileFuncInvoked)
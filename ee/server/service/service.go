@@ -20,6 +20,7 @@ type Service struct {
 	ds                fleet.Datastore
 	logger            kitlog.Logger
 	config            config.FleetConfig
+	mailService       fleet.MailService
 	clock             clock.Clock
 	authz             *authz.Authorizer
 	depStorage        storage.AllDEPStorage
@@ -53,6 +54,7 @@ func NewService(
 		ds:                ds,
 		logger:            logger,
 		config:            config,
+		mailService:       mailService,
 		clock:             c,
 		authz:             authorizer,
 		depStorage:        depStorage,
@@ -82,6 +82,9 @@ func (svc *Service) NewTeam(ctx context.Context, p fleet.TeamPayload) (*fleet.Te
 		if len(p.Secrets) > fleet.MaxEnrollSecretsCount {
 			return nil, fleet.NewInvalidArgumentError("secrets", "too many secrets")
 		}
+		if err := fleet.ValidateEnrollSecretHostRules(ctx, svc.ds, p.Secrets); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "validate enroll secrets")
+		}
 		team.Secrets = p.Secrets
 	} else {
 		// Set up a default enroll secret
@@ -96,10 +99,66 @@ func (svc *Service) NewTeam(ctx context.Context, p fleet.TeamPayload) (*fleet.Te
 		return nil, fleet.NewInvalidArgumentError("host_expiry_window", "must be greater than 0")
 	}
 
+	if p.FileRetrieval != nil {
+		if err := p.FileRetrieval.Validate(); err != nil {
+			return nil, fleet.NewInvalidArgumentError("file_retrieval.allowed_paths", err.Error())
+		}
+		team.Config.FileRetrieval = *p.FileRetrieval
+	}
+
+	if p.LockWipeSettings != nil {
+		team.Config.LockWipeSettings = *p.LockWipeSettings
+	}
+
+	if p.RemoteQueries != nil {
+		team.Config.RemoteQueries = *p.RemoteQueries
+	}
+
+	if p.VulnerabilitySLA != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		p.VulnerabilitySLA.Validate("vulnerability_sla", invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.VulnerabilitySLA = *p.VulnerabilitySLA
+	}
+
+	if p.Localization != nil {
+		if err := p.Localization.Validate(); err != nil {
+			return nil, fleet.NewInvalidArgumentError("localization.locale", err.Error())
+		}
+		team.Config.Localization = *p.Localization
+	}
+
+	if p.Branding != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		p.Branding.ValidateBranding("branding", invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.Branding = *p.Branding
+	}
+
+	if p.InheritedSettings != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		fleet.ValidateInheritedSettings(*p.InheritedSettings, invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.InheritedSettings = *p.InheritedSettings
+	}
+
 	team, err = svc.ds.NewTeam(ctx, team)
 	if err != nil {
 		return nil, err
 	}
+	// the teams created by the user of a tenant join the tenant
+	if user := authz.UserFromContext(ctx); user != nil && user.TenantID != nil {
+		if err := svc.ds.SetTeamsTenant(ctx, user.TenantID, []uint{team.ID}); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "set team tenant")
+		}
+	}
+	team.ApplyInheritedSettings(globalConfig)
 
 	if err := svc.ds.NewActivity(
 		ctx,
@@ -244,6 +303,55 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 		team.Config.HostExpirySettings = *payload.HostExpirySettings
 	}
 
+	if payload.FileRetrieval != nil {
+		if err := payload.FileRetrieval.Validate(); err != nil {
+			return nil, fleet.NewInvalidArgumentError("file_retrieval.allowed_paths", err.Error())
+		}
+		team.Config.FileRetrieval = *payload.FileRetrieval
+	}
+
+	if payload.LockWipeSettings != nil {
+		team.Config.LockWipeSettings = *payload.LockWipeSettings
+	}
+
+	if payload.RemoteQueries != nil {
+		team.Config.RemoteQueries = *payload.RemoteQueries
+	}
+
+	if payload.VulnerabilitySLA != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		payload.VulnerabilitySLA.Validate("vulnerability_sla", invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.VulnerabilitySLA = *payload.VulnerabilitySLA
+	}
+
+	if payload.Localization != nil {
+		if err := payload.Localization.Validate(); err != nil {
+			return nil, fleet.NewInvalidArgumentError("localization.locale", err.Error())
+		}
+		team.Config.Localization = *payload.Localization
+	}
+
+	if payload.Branding != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		payload.Branding.ValidateBranding("branding", invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.Branding = *payload.Branding
+	}
+
+	if payload.InheritedSettings != nil {
+		invalid := &fleet.InvalidArgumentError{}
+		fleet.ValidateInheritedSettings(*payload.InheritedSettings, invalid)
+		if invalid.HasErrors() {
+			return nil, ctxerr.Wrap(ctx, invalid)
+		}
+		team.Config.InheritedSettings = *payload.InheritedSettings
+	}
+
 	team, err = svc.ds.SaveTeam(ctx, team)
 	if err != nil {
 		return nil, err
@@ -318,6 +426,10 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 			return nil, ctxerr.Wrap(ctx, err, "update macos setup enable end user auth")
 		}
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, &team.ID, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record team config version")
+	}
+	team.ApplyInheritedSettings(appCfg)
 	return team, err
 }
 
@@ -368,6 +480,9 @@ func (svc *Service) ModifyTeamAgentOptions(ctx context.Context, teamID uint, tea
 	); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "create edited agent options activity")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, &tm.ID, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record team config version")
+	}
 
 	return tm, nil
 }
@@ -480,6 +595,14 @@ func (svc *Service) ListTeams(ctx context.Context, opt fleet.ListOptions) ([]*fl
 		return nil, err
 	}
 
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range teams {
+		team.ApplyInheritedSettings(appCfg)
+	}
+
 	return teams, nil
 }
 
@@ -606,6 +729,12 @@ func (svc *Service) GetTeam(ctx context.Context, teamID uint) (*fleet.Team, erro
 		return nil, err
 	}
 
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	team.ApplyInheritedSettings(appCfg)
+
 	return team, nil
 }
 
@@ -658,9 +787,14 @@ func (svc *Service) ModifyTeamEnrollSecrets(ctx context.Context, teamID uint, se
 	var newSecrets []*fleet.EnrollSecret
 	for _, secret := range secrets {
 		newSecrets = append(newSecrets, &fleet.EnrollSecret{
-			Secret: secret.Secret,
+			Secret:       secret.Secret,
+			Labels:       secret.Labels,
+			CustomFields: secret.CustomFields,
 		})
 	}
+	if err := fleet.ValidateEnrollSecretHostRules(ctx, svc.ds, newSecrets); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate enroll secrets")
+	}
 	if err := svc.ds.ApplyEnrollSecrets(ctx, ptr.Uint(teamID), newSecrets); err != nil {
 		return nil, err
 	}
@@ -751,7 +885,9 @@ func (svc *Service) ApplyTeamSpecs(ctx context.Context, specs []*fleet.TeamSpec,
 		var secrets []*fleet.EnrollSecret
 		for _, secret := range spec.Secrets {
 			secrets = append(secrets, &fleet.EnrollSecret{
-				Secret: secret.Secret,
+				Secret:       secret.Secret,
+				Labels:       secret.Labels,
+				CustomFields: secret.CustomFields,
 			})
 		}
 
@@ -783,6 +919,9 @@ func (svc *Service) ApplyTeamSpecs(ctx context.Context, specs []*fleet.TeamSpec,
 		if len(spec.Secrets) > fleet.MaxEnrollSecretsCount {
 			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("secrets", "too many secrets"), "validate secrets")
 		}
+		if err := fleet.ValidateEnrollSecretHostRules(ctx, svc.ds, secrets); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "validate secrets")
+		}
 		if err := spec.MDM.MacOSUpdates.Validate(); err != nil {
 			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("macos_updates", err.Error()))
 		}
@@ -831,6 +970,11 @@ func (svc *Service) ApplyTeamSpecs(ctx context.Context, specs []*fleet.TeamSpec,
 		}
 
 		if !applyOpts.DryRun {
+			for _, tm := range details {
+				if _, err := svc.ds.NewConfigVersion(ctx, &tm.ID, authz.UserFromContext(ctx)); err != nil {
+					return nil, ctxerr.Wrap(ctx, err, "record team config version")
+				}
+			}
 			if err := svc.ds.NewActivity(
 				ctx,
 				authz.UserFromContext(ctx),
@@ -908,11 +1052,55 @@ func (svc *Service) createTeamFromSpec(
 		hostExpirySettings = *spec.HostExpirySettings
 	}
 
+	var fileRetrieval fleet.FileRetrievalSettings
+	if spec.FileRetrieval != nil {
+		if err := spec.FileRetrieval.Validate(); err != nil {
+			invalid.Append("file_retrieval.allowed_paths", err.Error())
+		}
+		fileRetrieval = *spec.FileRetrieval
+	}
+
+	var lockWipeSettings fleet.LockWipeSettings
+	if spec.LockWipeSettings != nil {
+		lockWipeSettings = *spec.LockWipeSettings
+	}
+
+	var remoteQueries fleet.RemoteQuerySettings
+	if spec.RemoteQueries != nil {
+		remoteQueries = *spec.RemoteQueries
+	}
+
+	var vulnerabilitySLA fleet.VulnerabilitySLASettings
+	if spec.VulnerabilitySLA != nil {
+		spec.VulnerabilitySLA.Validate("vulnerability_sla", invalid)
+		vulnerabilitySLA = *spec.VulnerabilitySLA
+	}
+
+	var localization fleet.LocalizationSettings
+	if spec.Localization != nil {
+		if err := spec.Localization.Validate(); err != nil {
+			invalid.Append("localization.locale", err.Error())
+		}
+		localization = *spec.Localization
+	}
+
+	var branding fleet.OrgInfo
+	if spec.Branding != nil {
+		spec.Branding.ValidateBranding("branding", invalid)
+		branding = *spec.Branding
+	}
+
 	var hostStatusWebhook *fleet.HostStatusWebhookSettings
 	if spec.WebhookSettings.HostStatusWebhook != nil {
 		fleet.ValidateEnabledHostStatusIntegrations(*spec.WebhookSettings.HostStatusWebhook, invalid)
 		hostStatusWebhook = spec.WebhookSettings.HostStatusWebhook
 	}
+
+	var inheritedSettings []string
+	if spec.InheritedSettings != nil {
+		fleet.ValidateInheritedSettings(*spec.InheritedSettings, invalid)
+		inheritedSettings = *spec.InheritedSettings
+	}
 	if invalid.HasErrors() {
 		return nil, ctxerr.Wrap(ctx, invalid)
 	}
@@ -934,6 +1122,13 @@ func (svc *Service) createTeamFromSpec(
 				MacOSSetup:           macOSSetup,
 			},
 			HostExpirySettings: hostExpirySettings,
+			FileRetrieval:      fileRetrieval,
+			LockWipeSettings:   lockWipeSettings,
+			RemoteQueries:      remoteQueries,
+			VulnerabilitySLA:   vulnerabilitySLA,
+			Localization:       localization,
+			Branding:           branding,
+			InheritedSettings:  inheritedSettings,
 			WebhookSettings: fleet.TeamWebhookSettings{
 				HostStatusWebhook: hostStatusWebhook,
 			},
@@ -1094,12 +1289,56 @@ func (svc *Service) editTeamFromSpec(
 		team.Config.HostExpirySettings = *spec.HostExpirySettings
 	}
 
+	// if file_retrieval is not provided, do not change it
+	if spec.FileRetrieval != nil {
+		if err := spec.FileRetrieval.Validate(); err != nil {
+			invalid.Append("file_retrieval.allowed_paths", err.Error())
+		}
+		team.Config.FileRetrieval = *spec.FileRetrieval
+	}
+
+	// if lock_wipe_settings is not provided, do not change it
+	if spec.LockWipeSettings != nil {
+		team.Config.LockWipeSettings = *spec.LockWipeSettings
+	}
+
+	// if remote_queries is not provided, do not change it
+	if spec.RemoteQueries != nil {
+		team.Config.RemoteQueries = *spec.RemoteQueries
+	}
+
+	// if vulnerability_sla is not provided, do not change it
+	if spec.VulnerabilitySLA != nil {
+		spec.VulnerabilitySLA.Validate("vulnerability_sla", invalid)
+		team.Config.VulnerabilitySLA = *spec.VulnerabilitySLA
+	}
+
+	// if localization is not provided, do not change it
+	if spec.Localization != nil {
+		if err := spec.Localization.Validate(); err != nil {
+			invalid.Append("localization.locale", err.Error())
+		}
+		team.Config.Localization = *spec.Localization
+	}
+
+	// if branding is not provided, do not change it
+	if spec.Branding != nil {
+		spec.Branding.ValidateBranding("branding", invalid)
+		team.Config.Branding = *spec.Branding
+	}
+
 	// If host status webhook is not provided, do not change it
 	if spec.WebhookSettings.HostStatusWebhook != nil {
 		fleet.ValidateEnabledHostStatusIntegrations(*spec.WebhookSettings.HostStatusWebhook, invalid)
 		team.Config.WebhookSettings.HostStatusWebhook = spec.WebhookSettings.HostStatusWebhook
 	}
 
+	// if inherited_settings is not provided, do not change it
+	if spec.InheritedSettings != nil {
+		fleet.ValidateInheritedSettings(*spec.InheritedSettings, invalid)
+		team.Config.InheritedSettings = *spec.InheritedSettings
+	}
+
 	if spec.Integrations.GoogleCalendar != nil {
 		err = svc.validateTeamCalendarIntegrations(spec.Integrations.GoogleCalendar, appCfg, dryRun, invalid)
 		if err != nil {
@@ -1206,6 +1445,11 @@ func (svc *Service) applyTeamMacOSSettings(ctx context.Context, spec *fleet.Team
 	if err != nil {
 		return fleet.NewUserMessageError(err, http.StatusBadRequest)
 	}
+	if setFields["erase_device"] {
+		if err := applyUpon.EraseDevice.Validate(); err != nil {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("macos_settings.erase_device", err.Error()))
+		}
+	}
 
 	appCfg, err := svc.ds.AppConfig(ctx)
 	if err != nil {
@@ -24,3 +24,9 @@ func (svc *Service) ListVulnerabilities(ctx context.Context, opt fleet.VulnListO
 func (svc *Service) Vulnerability(ctx context.Context, cve string, teamID *uint, useCVSScores bool) (*fleet.VulnerabilityWithMetadata, error) {
 	return svc.Service.Vulnerability(ctx, cve, teamID, true)
 }
+
+func (svc *Service) ExportVulnerabilities(ctx context.Context, opt fleet.VulnListOptions, format fleet.DataExportFormat) (*fleet.DataExport, error) {
+	opt.ValidSortColumns = eeValidVulnSortColumns
+	opt.IsEE = true
+	return svc.Service.ExportVulnerabilities(ctx, opt, format)
+}
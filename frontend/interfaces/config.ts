@@ -43,6 +43,11 @@ export interface IMdmConfig {
   macos_settings: {
     custom_settings: null;
     enable_disk_encryption: boolean;
+    erase_device?: {
+      obliteration_behavior: string;
+      pin_length: number;
+      skip_pin: boolean;
+    };
   };
   macos_setup: {
     bootstrap_package: string | null;
@@ -74,6 +79,7 @@ export interface IConfigServerSettings {
   server_url: string;
   live_query_disabled: boolean;
   enable_analytics: boolean;
+  detailed_usage_analytics: boolean;
   deferred_save_host: boolean;
   query_reports_disabled: boolean;
   scripts_disabled: boolean;
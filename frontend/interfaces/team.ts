@@ -52,6 +52,11 @@ export interface ITeam extends ITeamSummary {
     macos_settings: {
       custom_settings: null; // TODO: types?
       enable_disk_encryption: boolean;
+      erase_device?: {
+        obliteration_behavior: string;
+        pin_length: number;
+        skip_pin: boolean;
+      };
     };
     macos_setup: {
       bootstrap_package: string | null;
@@ -972,6 +972,7 @@ const HostDetailsPage = ({
             id={host.id}
             platform={host.platform}
             hostName={host.display_name}
+            hostSerial={host.hardware_serial}
             onSuccess={() => setHostMdmDeviceState("locking")}
             onClose={() => setShowLockHostModal(false)}
           />
@@ -991,6 +992,7 @@ const HostDetailsPage = ({
           <WipeModal
             id={host.id}
             hostName={host.display_name}
+            hostSerial={host.hardware_serial}
             onSuccess={() => setHostMdmDeviceState("wiping")}
             onClose={() => setShowWipeModal(false)}
           />
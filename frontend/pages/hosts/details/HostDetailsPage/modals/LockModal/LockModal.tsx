@@ -6,7 +6,7 @@ import hostAPI from "services/entities/hosts";
 
 import Modal from "components/Modal";
 import Button from "components/buttons/Button";
-import Checkbox from "components/forms/fields/Checkbox";
+import InputField from "components/forms/fields/InputField";
 
 const baseClass = "lock-modal";
 
@@ -14,6 +14,7 @@ interface ILockModalProps {
   id: number;
   platform: string;
   hostName: string;
+  hostSerial: string;
   onSuccess: () => void;
   onClose: () => void;
 }
@@ -22,19 +23,27 @@ const LockModal = ({
   id,
   platform,
   hostName,
+  hostSerial,
   onSuccess,
   onClose,
 }: ILockModalProps) => {
   const { renderFlash } = useContext(NotificationContext);
-  const [lockChecked, setLockChecked] = React.useState(false);
+  const [confirmation, setConfirmation] = React.useState("");
   const [isLocking, setIsLocking] = React.useState(false);
 
   const onLock = async () => {
     setIsLocking(true);
     try {
-      await hostAPI.lockHost(id);
-      onSuccess();
-      renderFlash("success", "Host is locking!");
+      const resp = await hostAPI.lockHost(id, confirmation);
+      if (resp?.pending_approval) {
+        renderFlash(
+          "success",
+          "The lock request must be approved by another user."
+        );
+      } else {
+        onSuccess();
+        renderFlash("success", "Host is locking!");
+      }
     } catch (e) {
       renderFlash("error", getErrorReason(e));
     }
@@ -51,16 +60,32 @@ const LockModal = ({
             <p>Fleet will generate a six-digit unlock PIN.</p>
           )}
           <div className={`${baseClass}__confirm-message`}>
-            <span>
-              <b>Please check to confirm:</b>
-            </span>
-            <Checkbox
-              wrapperClassName={`${baseClass}__lock-checkbox`}
-              value={lockChecked}
-              onChange={(value: boolean) => setLockChecked(value)}
-            >
-              I wish to lock <b>{hostName}</b>
-            </Checkbox>
+            <InputField
+              autofocus
+              name="confirmation"
+              label={
+                <>
+                  To lock <b>{hostName}</b>, type{" "}
+                  {hostSerial ? (
+                    <>
+                      the serial number <b>{hostSerial}</b>
+                    </>
+                  ) : (
+                    "the confirmation token"
+                  )}
+                  :
+                </>
+              }
+              onChange={(value: string) => setConfirmation(value)}
+              value={confirmation}
+              helpText={
+                hostSerial
+                  ? undefined
+                  : "The confirmation token is shown after a first attempt to lock the host."
+              }
+              blockAutoComplete
+              ignore1password
+            />
           </div>
         </div>
 
@@ -70,7 +95,7 @@ const LockModal = ({
             onClick={onLock}
             variant="brand"
             className="delete-loading"
-            disabled={!lockChecked}
+            disabled={!confirmation.trim()}
             isLoading={isLocking}
           >
             Done
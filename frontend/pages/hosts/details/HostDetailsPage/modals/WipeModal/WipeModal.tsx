@@ -5,7 +5,7 @@ import { getErrorReason } from "interfaces/errors";
 
 import Modal from "components/Modal";
 import Button from "components/buttons/Button";
-import Checkbox from "components/forms/fields/Checkbox";
+import InputField from "components/forms/fields/InputField";
 import { NotificationContext } from "context/notification";
 
 const baseClass = "wipe-modal";
@@ -13,21 +13,35 @@ const baseClass = "wipe-modal";
 interface IWipeModalProps {
   id: number;
   hostName: string;
+  hostSerial: string;
   onSuccess: () => void;
   onClose: () => void;
 }
 
-const WipeModal = ({ id, hostName, onSuccess, onClose }: IWipeModalProps) => {
+const WipeModal = ({
+  id,
+  hostName,
+  hostSerial,
+  onSuccess,
+  onClose,
+}: IWipeModalProps) => {
   const { renderFlash } = useContext(NotificationContext);
-  const [lockChecked, setLockChecked] = React.useState(false);
+  const [confirmation, setConfirmation] = React.useState("");
   const [isWiping, setIsWiping] = React.useState(false);
 
   const onWipe = async () => {
     setIsWiping(true);
     try {
-      await hostAPI.wipeHost(id);
-      onSuccess();
-      renderFlash("success", "Success! Host is wiping.");
+      const resp = await hostAPI.wipeHost(id, confirmation);
+      if (resp?.pending_approval) {
+        renderFlash(
+          "success",
+          "The wipe request must be approved by another user."
+        );
+      } else {
+        onSuccess();
+        renderFlash("success", "Success! Host is wiping.");
+      }
     } catch (e) {
       renderFlash("error", getErrorReason(e));
     }
@@ -41,16 +55,32 @@ const WipeModal = ({ id, hostName, onSuccess, onClose }: IWipeModalProps) => {
         <div className={`${baseClass}__modal-content`}>
           <p>All content will be erased on this host.</p>
           <div className={`${baseClass}__confirm-message`}>
-            <span>
-              <b>Please check to confirm:</b>
-            </span>
-            <Checkbox
-              wrapperClassName={`${baseClass}__wipe-checkbox`}
-              value={lockChecked}
-              onChange={(value: boolean) => setLockChecked(value)}
-            >
-              I wish to wipe <b>{hostName}</b>
-            </Checkbox>
+            <InputField
+              autofocus
+              name="confirmation"
+              label={
+                <>
+                  To wipe <b>{hostName}</b>, type{" "}
+                  {hostSerial ? (
+                    <>
+                      the serial number <b>{hostSerial}</b>
+                    </>
+                  ) : (
+                    "the confirmation token"
+                  )}
+                  :
+                </>
+              }
+              onChange={(value: string) => setConfirmation(value)}
+              value={confirmation}
+              helpText={
+                hostSerial
+                  ? undefined
+                  : "The confirmation token is shown after a first attempt to wipe the host."
+              }
+              blockAutoComplete
+              ignore1password
+            />
           </div>
         </div>
 
@@ -60,7 +90,7 @@ const WipeModal = ({ id, hostName, onSuccess, onClose }: IWipeModalProps) => {
             onClick={onWipe}
             variant="alert"
             className="delete-loading"
-            disabled={!lockChecked}
+            disabled={!confirmation.trim()}
             isLoading={isWiping}
           >
             Wipe
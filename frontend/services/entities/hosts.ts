@@ -40,6 +40,10 @@ export type IUnlockHostResponse =
     }
   | Record<string, never>;
 
+/** The lock and wipe requests are pending approval when the host's team
+ * requires a second approver. */
+export type ILockWipeHostResponse = { pending_approval?: boolean } | undefined;
+
 // the source of truth for the filter option names.
 // there are used on many other pages but we define them here.
 // TODO: add other filter options here.
@@ -510,9 +514,12 @@ export default {
     return sendRequest("GET", HOST_ENCRYPTION_KEY(id));
   },
 
-  lockHost: (id: number) => {
+  lockHost: (
+    id: number,
+    confirmation: string
+  ): Promise<ILockWipeHostResponse> => {
     const { HOST_LOCK } = endpoints;
-    return sendRequest("POST", HOST_LOCK(id));
+    return sendRequest("POST", HOST_LOCK(id), { confirmation });
   },
 
   unlockHost: (id: number): Promise<IUnlockHostResponse> => {
@@ -520,9 +527,12 @@ export default {
     return sendRequest("POST", HOST_UNLOCK(id));
   },
 
-  wipeHost: (id: number) => {
+  wipeHost: (
+    id: number,
+    confirmation: string
+  ): Promise<ILockWipeHostResponse> => {
     const { HOST_WIPE } = endpoints;
-    return sendRequest("POST", HOST_WIPE(id));
+    return sendRequest("POST", HOST_WIPE(id), { confirmation });
   },
 
   resendProfile: (hostId: number, profileUUID: string) => {
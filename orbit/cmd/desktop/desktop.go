@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/systray"
@@ -120,15 +122,23 @@ func main() {
 		versionItem.Disable()
 		systray.AddSeparator()
 
-		migrateMDMItem := systray.AddMenuItem("Migrate to Fleet", "")
+		// messages are the localized titles of the menu items, they are
+		// updated with every desktop summary received from the server.
+		var messages atomic.Value
+		messages.Store(fleet.LocalizedMessages(nil))
+		msg := func(key fleet.MessageKey) string {
+			return messages.Load().(fleet.LocalizedMessages).Get(key)
+		}
+
+		migrateMDMItem := systray.AddMenuItem(msg(fleet.MessageDesktopMigrateMDM), "")
 		migrateMDMItem.Disable()
 		// this item is only shown if certain conditions are met below.
 		migrateMDMItem.Hide()
 
-		myDeviceItem := systray.AddMenuItem("Connecting...", "")
+		myDeviceItem := systray.AddMenuItem(msg(fleet.MessageDesktopConnecting), "")
 		myDeviceItem.Disable()
 
-		transparencyItem := systray.AddMenuItem("Transparency", "")
+		transparencyItem := systray.AddMenuItem(msg(fleet.MessageDesktopTransparency), "")
 		transparencyItem.Disable()
 
 		tokenReader := token.Reader{Path: identifierPath}
@@ -172,7 +182,7 @@ func main() {
 
 		disableTray := func() {
 			log.Debug().Msg("disabling tray items")
-			myDeviceItem.SetTitle("Connecting...")
+			myDeviceItem.SetTitle(msg(fleet.MessageDesktopConnecting))
 			myDeviceItem.Disable()
 			transparencyItem.Disable()
 			migrateMDMItem.Disable()
@@ -191,11 +201,16 @@ func main() {
 
 				for {
 					refetchToken()
-					_, err := client.DesktopSummary(tokenReader.GetCached())
+					sum, err := client.DesktopSummary(tokenReader.GetCached())
 
 					if err == nil || errors.Is(err, service.ErrMissingLicense) {
+						if sum != nil {
+							messages.Store(sum.Config.Messages)
+							transparencyItem.SetTitle(msg(fleet.MessageDesktopTransparency))
+							migrateMDMItem.SetTitle(msg(fleet.MessageDesktopMigrateMDM))
+						}
 						log.Debug().Msg("enabling tray items")
-						myDeviceItem.SetTitle("My device")
+						myDeviceItem.SetTitle(msg(fleet.MessageDesktopMyDevice))
 						myDeviceItem.Enable()
 						transparencyItem.Enable()
 						return
@@ -285,7 +300,7 @@ func main() {
 				case err == nil:
 					// OK
 				case errors.Is(err, service.ErrMissingLicense):
-					myDeviceItem.SetTitle("My device")
+					myDeviceItem.SetTitle(msg(fleet.MessageDesktopMyDevice))
 					continue
 				case errors.Is(err, service.ErrUnauthenticated):
 					disableTray()
@@ -296,6 +311,12 @@ func main() {
 					continue
 				}
 
+				// update the localized titles in case the locale or the
+				// translations changed
+				messages.Store(sum.Config.Messages)
+				transparencyItem.SetTitle(msg(fleet.MessageDesktopTransparency))
+				migrateMDMItem.SetTitle(msg(fleet.MessageDesktopMigrateMDM))
+
 				failingPolicies := 0
 				if sum.FailingPolicies != nil {
 					failingPolicies = int(*sum.FailingPolicies)
@@ -306,18 +327,18 @@ func main() {
 						// Windows (or maybe just the systray library?) doesn't support color emoji
 						// in the system tray menu, so we use text as an alternative.
 						if failingPolicies == 1 {
-							myDeviceItem.SetTitle("My device (1 issue)")
+							myDeviceItem.SetTitle(msg(fleet.MessageDesktopMyDeviceOneIssue))
 						} else {
-							myDeviceItem.SetTitle(fmt.Sprintf("My device (%d issues)", failingPolicies))
+							myDeviceItem.SetTitle(sum.Config.Messages.Format(fleet.MessageDesktopMyDeviceIssues, "count", strconv.Itoa(failingPolicies)))
 						}
 					} else {
-						myDeviceItem.SetTitle(fmt.Sprintf("🔴 My device (%d)", failingPolicies))
+						myDeviceItem.SetTitle(fmt.Sprintf("🔴 %s (%d)", msg(fleet.MessageDesktopMyDevice), failingPolicies))
 					}
 				} else {
 					if runtime.GOOS == "windows" {
-						myDeviceItem.SetTitle("My device")
+						myDeviceItem.SetTitle(msg(fleet.MessageDesktopMyDevice))
 					} else {
-						myDeviceItem.SetTitle("🟢 My device")
+						myDeviceItem.SetTitle("🟢 " + msg(fleet.MessageDesktopMyDevice))
 					}
 				}
 				myDeviceItem.Enable()
@@ -353,6 +374,7 @@ func main() {
 						// update org info in case it changed
 						mdmMigrator.SetProps(useraction.MDMMigratorProps{
 							OrgInfo:     sum.Config.OrgInfo,
+							Messages:    sum.Config.Messages,
 							IsUnmanaged: isUnmanaged,
 						})
 
@@ -50,9 +50,10 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	plugins := orbittable.OrbitDefaultTables()
-	plugins = append(plugins, orbittable.PlatformTables()...)
-	server.RegisterPlugin(plugins...)
+	registry := orbittable.NewRegistry()
+	registry.Register(orbittable.OrbitDefaultTables()...)
+	registry.Register(orbittable.PlatformTables()...)
+	server.RegisterPlugin(registry.Plugins()...)
 	if err := server.Run(); err != nil {
 		log.Fatalln(err)
 	}
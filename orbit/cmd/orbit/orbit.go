@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/orbit/pkg/augeas"
@@ -28,9 +29,13 @@ import (
 	"github.com/fleetdm/fleet/v4/orbit/pkg/osquery"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/osservice"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/platform"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/powerevents"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/profiles"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/scripts"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/servicerecovery"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/orbit_info"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/tamper"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/token"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/update"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/update/filestore"
@@ -180,6 +185,12 @@ func main() {
 			Usage:   "Enable script execution",
 			EnvVars: []string{"ORBIT_ENABLE_SCRIPTS"},
 		},
+		&cli.DurationFlag{
+			Name:    "power-event-hook-timeout",
+			Usage:   "Maximum duration given to flush the pending results when the host is about to go to sleep or shut down",
+			Value:   powerevents.DefaultTimeout,
+			EnvVars: []string{"ORBIT_POWER_EVENT_HOOK_TIMEOUT"},
+		},
 		&cli.StringFlag{
 			Name:    "host-identifier",
 			Usage:   "Sets the host identifier that orbit and osquery will use when enrolling to Fleet. Options: 'uuid' and 'instance' (requires Fleet >= v4.42.0)",
@@ -202,6 +213,16 @@ func main() {
 			Usage:   "Sets a custom osquery database directory, it must be an absolute path",
 			EnvVars: []string{"ORBIT_OSQUERY_DB"},
 		},
+		&cli.StringSliceFlag{
+			Name:    "enable-tables",
+			Usage:   "Enables the given fleetd extension tables, including those disabled by default",
+			EnvVars: []string{"ORBIT_ENABLE_TABLES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disable-tables",
+			Usage:   "Disables the given fleetd extension tables",
+			EnvVars: []string{"ORBIT_DISABLE_TABLES"},
+		},
 	}
 	app.Before = func(c *cli.Context) error {
 		// handle old installations, which had default root dir set to /var/lib/orbit
@@ -445,10 +466,14 @@ func main() {
 		// Setting up the system service management early on the process lifetime
 		appDoneCh = make(chan struct{})
 
+		// Hooks run when the host is about to go to sleep or shut down, the
+		// hooks are registered once their dependencies are created.
+		powerHooks := &powerevents.Hooks{Timeout: c.Duration("power-event-hook-timeout")}
+
 		// Initializing service runner and system service manager
 		systemChecker := newSystemChecker()
 		g.Add(systemChecker.Execute, systemChecker.Interrupt)
-		go osservice.SetupServiceManagement(constant.SystemServiceName, systemChecker.svcInterruptCh, appDoneCh)
+		go osservice.SetupServiceManagement(constant.SystemServiceName, systemChecker.svcInterruptCh, appDoneCh, powerHooks)
 
 		// sofwareupdated is a macOS daemon that automatically updates Apple software.
 		if c.Bool("disable-kickstart-softwareupdated") && runtime.GOOS == "darwin" {
@@ -650,6 +675,23 @@ func main() {
 			fleetURL = "https://" + fleetURL
 		}
 
+		fleetClientCertPath := filepath.Join(c.String("root-dir"), constant.FleetTLSClientCertificateFileName)
+		fleetClientKeyPath := filepath.Join(c.String("root-dir"), constant.FleetTLSClientKeyFileName)
+		fleetClientCrt, err := certificate.LoadClientCertificateFromFiles(fleetClientCertPath, fleetClientKeyPath)
+		if err != nil {
+			return fmt.Errorf("error loading fleet client certificate: %w", err)
+		}
+
+		var fleetClientCertificate *tls.Certificate
+		if fleetClientCrt != nil {
+			log.Info().Msg("Found TLS client certificate and key. Using them to authenticate to Fleet.")
+			fleetClientCertificate = &fleetClientCrt.Crt
+			options = append(options, osquery.WithFlags([]string{
+				"--tls_client_cert", fleetClientCertPath,
+				"--tls_client_key", fleetClientKeyPath,
+			}))
+		}
+
 		enrollSecret := c.String("enroll-secret")
 		if enrollSecret != "" {
 			const enrollSecretEnvName = "ENROLL_SECRET"
@@ -717,8 +759,10 @@ func main() {
 				osquery.WithFlags([]string{"--tls_server_certs", certPath}),
 			)
 		} else if fleetURL != "https://" {
-			if enrollSecret == "" {
-				return errors.New("enroll secret must be specified to connect to Fleet server")
+			// the TLS client certificate replaces the enroll secret if the
+			// Fleet server has certificate enrollment enabled.
+			if enrollSecret == "" && fleetClientCertificate == nil {
+				return errors.New("enroll secret or TLS client certificate must be specified to connect to Fleet server")
 			}
 
 			parsedURL, err := url.Parse(fleetURL)
@@ -758,23 +802,6 @@ func main() {
 
 		}
 
-		fleetClientCertPath := filepath.Join(c.String("root-dir"), constant.FleetTLSClientCertificateFileName)
-		fleetClientKeyPath := filepath.Join(c.String("root-dir"), constant.FleetTLSClientKeyFileName)
-		fleetClientCrt, err := certificate.LoadClientCertificateFromFiles(fleetClientCertPath, fleetClientKeyPath)
-		if err != nil {
-			return fmt.Errorf("error loading fleet client certificate: %w", err)
-		}
-
-		var fleetClientCertificate *tls.Certificate
-		if fleetClientCrt != nil {
-			log.Info().Msg("Found TLS client certificate and key. Using them to authenticate to Fleet.")
-			fleetClientCertificate = &fleetClientCrt.Crt
-			options = append(options, osquery.WithFlags([]string{
-				"--tls_client_cert", fleetClientCertPath,
-				"--tls_client_key", fleetClientKeyPath,
-			}))
-		}
-
 		orbitClient, err := service.NewOrbitClient(
 			c.String("root-dir"),
 			fleetURL,
@@ -802,11 +829,27 @@ func main() {
 			renewEnrollmentProfileCommandFrequency = time.Hour
 			windowsMDMEnrollmentCommandFrequency   = time.Hour
 			windowsMDMBitlockerCommandFrequency    = time.Hour
+			linuxDiskEncryptionEscrowFrequency     = time.Hour
+			// the user is shown the guidance at most this often
+			linuxDiskEncryptionEnforcementFrequency = 4 * time.Hour
 		)
 		configFetcher := update.ApplyRenewEnrollmentProfileConfigFetcherMiddleware(orbitClient, renewEnrollmentProfileCommandFrequency, fleetURL)
+		// the feature flags computed by Fleet for this host, consulted by the
+		// runners that roll out new behaviors gradually.
+		featureFlags := &update.FeatureFlags{}
+		configFetcher = update.ApplyFeatureFlagsConfigFetcherMiddleware(configFetcher, featureFlags)
+		scriptsResultsDir := filepath.Join(c.String("root-dir"), constant.ScriptResultsDirName)
 		configFetcher, scriptsEnabledFn := update.ApplyRunScriptsConfigFetcherMiddleware(
-			configFetcher, c.Bool("enable-scripts"), orbitClient,
+			configFetcher, c.Bool("enable-scripts"), orbitClient, scriptsResultsDir,
 		)
+		powerHooks.Register("flush script results", func(ctx context.Context, _ powerevents.Event) error {
+			runner := &scripts.Runner{Client: orbitClient, ResultsDir: scriptsResultsDir}
+			return runner.FlushPendingResults(ctx)
+		})
+		configFetcher = update.ApplyLogCollectionConfigFetcherMiddleware(configFetcher, scriptsEnabledFn, orbitClient)
+		configFetcher = update.ApplyFileRetrievalConfigFetcherMiddleware(configFetcher, scriptsEnabledFn, orbitClient)
+		configFetcher = update.ApplyPprofCaptureConfigFetcherMiddleware(configFetcher, orbitClient)
+		configFetcher = update.ApplyRemoteQueryConfigFetcherMiddleware(configFetcher, orbitClient, osquery.ExtensionSocketPath(c.String("root-dir")))
 
 		switch runtime.GOOS {
 		case "darwin":
@@ -821,6 +864,16 @@ func main() {
 		case "windows":
 			configFetcher = update.ApplyWindowsMDMEnrollmentFetcherMiddleware(configFetcher, windowsMDMEnrollmentCommandFrequency, orbitHostInfo.HardwareUUID, orbitClient)
 			configFetcher = update.ApplyWindowsMDMBitlockerFetcherMiddleware(configFetcher, windowsMDMBitlockerCommandFrequency, orbitClient)
+
+			// Apply the fleetd service recovery actions and run the watchdog
+			// configured in Fleet, and report to Fleet when fleetd was
+			// recovered after a failure.
+			serviceRecovery := servicerecovery.NewManager(c.String("root-dir"), orbitClient)
+			g.Add(serviceRecovery.Execute, serviceRecovery.Interrupt)
+			configFetcher = update.ApplyWindowsServiceConfigFetcherMiddleware(configFetcher, serviceRecovery)
+		case "linux":
+			configFetcher = update.ApplyLinuxDiskEncryptionEscrowFetcherMiddleware(configFetcher, linuxDiskEncryptionEscrowFrequency, orbitClient, c.String("root-dir"))
+			configFetcher = update.ApplyLinuxDiskEncryptionEnforcementFetcherMiddleware(configFetcher, linuxDiskEncryptionEnforcementFrequency, orbitClient)
 		}
 
 		const orbitFlagsUpdateInterval = 30 * time.Second
@@ -838,6 +891,11 @@ func main() {
 		}
 		g.Add(flagRunner.Execute, flagRunner.Interrupt)
 
+		// Report attempts to tamper with fleetd (osqueryd killed repeatedly,
+		// service disabled, configuration files modified) to Fleet.
+		tamperDetector := tamper.NewDetector(c.String("root-dir"), orbitClient)
+		g.Add(tamperDetector.Execute, tamperDetector.Interrupt)
+
 		if !c.Bool("disable-updates") {
 			const serverOverridesInterval = 30 * time.Second
 			serverOverridesRunner := newServerOverridesRunner(
@@ -1054,7 +1112,21 @@ func main() {
 		if err != nil {
 			return fmt.Errorf("create osquery runner: %w", err)
 		}
-		g.Add(r.Execute, r.Interrupt)
+		// osqueryd exiting without being interrupted by orbit (e.g. killed) is
+		// recorded, so that it is reported if it happens repeatedly.
+		var osquerydInterrupted atomic.Bool
+		g.Add(func() error {
+			err := r.Execute()
+			if !osquerydInterrupted.Load() {
+				if recordErr := tamper.RecordOsquerydExit(c.String("root-dir"), time.Now()); recordErr != nil {
+					log.Error().Err(recordErr).Msg("record osqueryd exit")
+				}
+			}
+			return err
+		}, func(err error) {
+			osquerydInterrupted.Store(true)
+			r.Interrupt(err)
+		})
 
 		// rootDir string, addr string, rootCA string, insecureSkipVerify bool, enrollSecret, uuid string
 		checkerClient, err := service.NewOrbitClient(
@@ -1109,6 +1181,8 @@ func main() {
 				startTime,
 				scriptsEnabledFn,
 			)),
+			table.WithEnabledTables(c.StringSlice("enable-tables")...),
+			table.WithDisabledTables(c.StringSlice("disable-tables")...),
 		)
 
 		if c.Bool("fleet-desktop") {
@@ -1176,10 +1250,26 @@ func main() {
 
 		go sigusrListener(c.String("root-dir"))
 
+		g.Add(func() error {
+			if err := powerevents.Listen(ctx, powerHooks); err != nil {
+				// not being notified of power events must not stop orbit
+				log.Info().Err(err).Msg("listen to power events failed")
+				<-ctx.Done()
+			}
+			return nil
+		}, func(error) {
+			cancel()
+		})
+
 		if err := g.Run(); err != nil {
 			log.Error().Err(err).Msg("unexpected exit")
 		}
 
+		// orbit is stopping, which is the case when the host shuts down
+		if err := powerHooks.Run(powerevents.Shutdown); err != nil {
+			log.Error().Err(err).Msg("run shutdown hooks")
+		}
+
 		close(appDoneCh) // Signal to indicate runners have just ended
 		return nil
 	}
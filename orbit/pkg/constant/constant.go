@@ -55,4 +55,8 @@ const (
 	// ServerOverridesFileName is the name of the file in the root directory
 	// that specifies the override configuration fetched from the server.
 	ServerOverridesFileName = "server-overrides.json"
+	// ScriptResultsDirName is the name of the directory in the root directory
+	// where the script results are persisted until they are saved by the
+	// Fleet server.
+	ScriptResultsDirName = "script-results"
 )
@@ -20,9 +20,9 @@ func WithEnv(name, value string) Option {
 
 // WithArg sets command line arguments for the application.
 //
-// TODO: for now CLI arguments are only used by the darwin
-// implementation, just because it's the only platform that needs
-// them.
+// TODO: for now CLI arguments are only used by the darwin and linux
+// implementations, just because they're the only platforms that need
+// them. On linux, the value is omitted if it is empty.
 func WithArg(name, value string) Option {
 	return func(a *eopts) {
 		a.args = append(a.args, [2]string{name, value})
@@ -14,9 +14,56 @@ import (
 
 // run uses sudo to run the given path as login user.
 func run(path string, opts eopts) error {
+	args, err := sudoArgs(path, opts)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sudo", args...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	log.Printf("cmd=%s", cmd.String())
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open path %q: %w", path, err)
+	}
+	return nil
+}
+
+// RunWithOutput runs the given path as the current login user, waits for it
+// to exit and returns its standard output and exit code. It is only available
+// on Linux, where it is used to prompt the user with zenity dialogs.
+func RunWithOutput(path string, opts ...Option) (output []byte, exitCode int, err error) {
+	var o eopts
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	args, err := sudoArgs(path, o)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	cmd := exec.Command("sudo", args...)
+	cmd.Stderr = os.Stderr
+	log.Printf("cmd=%s", cmd.String())
+
+	output, err = cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return output, exitErr.ExitCode(), nil
+		}
+		return nil, -1, fmt.Errorf("run path %q: %w", path, err)
+	}
+	return output, 0, nil
+}
+
+// sudoArgs returns the sudo arguments to run the given path as login user.
+func sudoArgs(path string, opts eopts) ([]string, error) {
 	user, err := getLoginUID()
 	if err != nil {
-		return fmt.Errorf("get user: %w", err)
+		return nil, fmt.Errorf("get user: %w", err)
 	}
 
 	log.Info().
@@ -48,15 +95,14 @@ func run(path string, opts eopts) error {
 		path,
 	)
 
-	cmd := exec.Command("sudo", arg...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	log.Printf("cmd=%s", cmd.String())
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("open path %q: %w", path, err)
+	// set the program arguments, the value is omitted for flags without one.
+	for _, nv := range opts.args {
+		arg = append(arg, nv[0])
+		if nv[1] != "" {
+			arg = append(arg, nv[1])
+		}
 	}
-	return nil
+	return arg, nil
 }
 
 type user struct {
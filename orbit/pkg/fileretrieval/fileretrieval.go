@@ -0,0 +1,156 @@
+// Package fileretrieval implements support to retrieve a file from the host
+// when requested by the Fleet server, e.g. for incident response.
+package fileretrieval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+)
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	GetHostFileRetrieval(execID string) (*fleet.HostFileRetrieval, error)
+	SaveHostFileRetrievalResult(result *fleet.HostFileRetrievalResultPayload) error
+}
+
+// Retriever is the type that processes file retrieval requests, taking care
+// of retrieving each request, reading the file and uploading its contents.
+// The path was checked against the allowed paths by the Fleet server when the
+// request was made, it is checked again once symbolic links are resolved
+// before the file is read.
+type Retriever struct {
+	Client Client
+	// Enabled indicates if this agent allows file retrieval. As it gives access
+	// to the host's filesystem, it follows the scripts execution setting. If it
+	// is false, an error result is sent for each request so that the Fleet
+	// server knows the agent processed it.
+	Enabled bool
+
+	// notifyFn can be set for tests to mock the notification of the end user.
+	// If nil, notify will be used, which has a different implementation on
+	// each platform.
+	notifyFn func(message string) error
+}
+
+// Run processes all file retrieval requests identified by the execution IDs.
+func (r *Retriever) Run(execIDs []string) error {
+	var errs []error
+	for _, execID := range execIDs {
+		if !r.Enabled {
+			if err := r.saveResult(&fleet.HostFileRetrievalResultPayload{
+				ExecutionID: execID,
+				Error:       "File retrieval is disabled. To retrieve files, deploy the fleetd agent with --enable-scripts.",
+			}); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		fr, err := r.Client.GetHostFileRetrieval(execID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get host file retrieval: %w", err))
+			continue
+		}
+		if err := r.runOne(fr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (r *Retriever) runOne(fr *fleet.HostFileRetrieval) error {
+	if fr.Status != fleet.HostFileRetrievalStatusPending {
+		// already a result stored for this request, skip, it shouldn't be sent
+		// again by Fleet.
+		return nil
+	}
+
+	result := &fleet.HostFileRetrievalResultPayload{ExecutionID: fr.ExecutionID}
+	data, err := readFile(fr.Path, fr.AllowedPaths)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Data = data
+	}
+	if err := r.saveResult(result); err != nil {
+		return err
+	}
+
+	if fr.NotifyEndUser && result.Error == "" {
+		notifyFn := r.notifyFn
+		if notifyFn == nil {
+			notifyFn = notify
+		}
+		msg := fmt.Sprintf("Your IT administrator retrieved the file %s from this computer.", fr.Path)
+		if err := notifyFn(msg); err != nil {
+			// the file was uploaded, do not fail the retrieval.
+			log.Info().Err(err).Msg("notify end user of file retrieval")
+		}
+	}
+	return nil
+}
+
+func (r *Retriever) saveResult(result *fleet.HostFileRetrievalResultPayload) error {
+	if err := r.Client.SaveHostFileRetrievalResult(result); err != nil {
+		return fmt.Errorf("save file retrieval result: %w", err)
+	}
+	return nil
+}
+
+// readFile returns the contents of the regular file at path. The file itself
+// cannot be a symbolic link, and the path with all symbolic links resolved
+// must be allowed by allowedPaths, so that a link inside of an allowed
+// directory (to the file or to one of its parent directories) cannot be used
+// to retrieve a file outside of it.
+func readFile(path string, allowedPaths []string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%s is a symbolic link", path)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > fleet.MaxHostFileRetrievalSize {
+		return nil, fmt.Errorf("file is too large: %d bytes (maximum is %d bytes)", info.Size(), fleet.MaxHostFileRetrievalSize)
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve symbolic links: %w", err)
+	}
+	settings := fleet.FileRetrievalSettings{AllowedPaths: allowedPaths}
+	if !settings.Allows(realPath, runtime.GOOS) {
+		return nil, fmt.Errorf("%s resolves to %s, which is not an allowed path", path, realPath)
+	}
+
+	f, err := os.Open(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	// the file may have grown since the stat, never read more than the limit.
+	data, err := io.ReadAll(io.LimitReader(f, fleet.MaxHostFileRetrievalSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > fleet.MaxHostFileRetrievalSize {
+		return nil, fmt.Errorf("file is too large: more than %d bytes", fleet.MaxHostFileRetrievalSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,226 @@
+package fileretrieval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+// tempDir returns a temporary directory with its symbolic links resolved
+// (e.g. /var is a link to /private/var on macOS).
+func tempDir(t *testing.T) string {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	return dir
+}
+
+func TestRetriever(t *testing.T) {
+	dir := tempDir(t)
+	allowed := []string{dir + string(filepath.Separator)}
+	filePath := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("content"), 0o600))
+	emptyPath := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0o600))
+	largePath := filepath.Join(dir, "large.bin")
+	f, err := os.Create(largePath)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(fleet.MaxHostFileRetrievalSize+1))
+	require.NoError(t, f.Close())
+
+	pending := func(path string, notify bool) *fleet.HostFileRetrieval {
+		return &fleet.HostFileRetrieval{Status: fleet.HostFileRetrievalStatusPending, Path: path, NotifyEndUser: notify, AllowedPaths: allowed}
+	}
+
+	cases := []struct {
+		desc    string
+		client  *mockClient
+		enabled bool
+		execIDs []string
+
+		errContains     string
+		wantData        map[string]string
+		wantResultError map[string]string
+		wantNotified    int
+	}{
+		{
+			desc:    "no exec ids",
+			client:  &mockClient{},
+			enabled: true,
+		},
+		{
+			desc: "success",
+			client: &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{
+				"a": pending(filePath, false),
+				"b": pending(emptyPath, true),
+			}},
+			enabled:      true,
+			execIDs:      []string{"a", "b"},
+			wantData:     map[string]string{"a": "content", "b": ""},
+			wantNotified: 1,
+		},
+		{
+			desc:            "disabled",
+			client:          &mockClient{},
+			enabled:         false,
+			execIDs:         []string{"a"},
+			wantResultError: map[string]string{"a": "File retrieval is disabled"},
+		},
+		{
+			desc:        "one unknown",
+			client:      &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{"a": pending(filePath, false)}},
+			enabled:     true,
+			execIDs:     []string{"b", "a"},
+			errContains: "no such file retrieval: b",
+			wantData:    map[string]string{"a": "content"},
+		},
+		{
+			desc: "already completed",
+			client: &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{
+				"a": {Status: fleet.HostFileRetrievalStatusCompleted, Path: filePath},
+			}},
+			enabled: true,
+			execIDs: []string{"a"},
+		},
+		{
+			desc: "read failures",
+			client: &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{
+				"missing": pending(filepath.Join(dir, "missing.txt"), true),
+				"dir":     pending(dir, false),
+				"large":   pending(largePath, false),
+			}},
+			enabled: true,
+			execIDs: []string{"missing", "dir", "large"},
+			wantResultError: map[string]string{
+				"missing": "stat file",
+				"dir":     "is not a regular file",
+				"large":   "file is too large",
+			},
+		},
+		{
+			desc: "not allowed anymore",
+			client: &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{
+				"a": {Status: fleet.HostFileRetrievalStatusPending, Path: filePath, AllowedPaths: []string{filepath.Join(dir, "other.txt")}},
+				"b": {Status: fleet.HostFileRetrievalStatusPending, Path: filePath},
+			}},
+			enabled: true,
+			execIDs: []string{"a", "b"},
+			wantResultError: map[string]string{
+				"a": "which is not an allowed path",
+				"b": "which is not an allowed path",
+			},
+		},
+		{
+			desc:        "save fails",
+			client:      &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{"a": pending(filePath, true)}, saveErr: io.ErrUnexpectedEOF},
+			enabled:     true,
+			execIDs:     []string{"a"},
+			errContains: "save file retrieval result: unexpected EOF",
+			wantData:    map[string]string{"a": "content"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			for id, fr := range c.client.retrievals {
+				fr.ExecutionID = id
+			}
+
+			var notified int
+			retriever := &Retriever{
+				Client:  c.client,
+				Enabled: c.enabled,
+				notifyFn: func(message string) error {
+					notified++
+					return errors.New("notifications are ignored")
+				},
+			}
+			err := retriever.Run(c.execIDs)
+			if c.errContains != "" {
+				require.ErrorContains(t, err, c.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.wantNotified, notified)
+
+			require.Len(t, c.client.results, len(c.wantData)+len(c.wantResultError))
+			for id, data := range c.wantData {
+				res := c.client.results[id]
+				require.NotNil(t, res, id)
+				require.Empty(t, res.Error)
+				require.Equal(t, data, string(res.Data))
+			}
+			for id, msg := range c.wantResultError {
+				res := c.client.results[id]
+				require.NotNil(t, res, id)
+				require.Contains(t, res.Error, msg)
+				require.Empty(t, res.Data)
+			}
+		})
+	}
+}
+
+func TestRetrieverSymlink(t *testing.T) {
+	dir := tempDir(t)
+	allowedDir := filepath.Join(dir, "allowed")
+	require.NoError(t, os.Mkdir(allowedDir, 0o700))
+	outsideDir := filepath.Join(dir, "outside")
+	require.NoError(t, os.Mkdir(outsideDir, 0o700))
+	target := filepath.Join(outsideDir, "secret.txt")
+	require.NoError(t, os.WriteFile(target, []byte("secret"), 0o600))
+
+	// a link to the file, and a link to its directory
+	fileLink := filepath.Join(allowedDir, "link.txt")
+	if err := os.Symlink(target, fileLink); err != nil {
+		t.Skipf("cannot create symlink: %v", err)
+	}
+	dirLink := filepath.Join(allowedDir, "dir")
+	require.NoError(t, os.Symlink(outsideDir, dirLink))
+	// a link to a directory that is allowed is followed
+	okLink := filepath.Join(allowedDir, "ok")
+	require.NoError(t, os.Symlink(filepath.Join(allowedDir, "sub"), okLink))
+	require.NoError(t, os.Mkdir(filepath.Join(allowedDir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(allowedDir, "sub", "ok.txt"), []byte("ok"), 0o600))
+
+	allowed := []string{allowedDir + string(filepath.Separator)}
+	client := &mockClient{retrievals: map[string]*fleet.HostFileRetrieval{
+		"file": {ExecutionID: "file", Status: fleet.HostFileRetrievalStatusPending, Path: fileLink, AllowedPaths: allowed},
+		"dir":  {ExecutionID: "dir", Status: fleet.HostFileRetrievalStatusPending, Path: filepath.Join(dirLink, "secret.txt"), AllowedPaths: allowed},
+		"ok":   {ExecutionID: "ok", Status: fleet.HostFileRetrievalStatusPending, Path: filepath.Join(okLink, "ok.txt"), AllowedPaths: allowed},
+	}}
+	retriever := &Retriever{Client: client, Enabled: true}
+	require.NoError(t, retriever.Run([]string{"file", "dir", "ok"}))
+
+	require.Contains(t, client.results["file"].Error, "is a symbolic link")
+	require.Empty(t, client.results["file"].Data)
+	require.Contains(t, client.results["dir"].Error, "resolves to "+target+", which is not an allowed path")
+	require.Empty(t, client.results["dir"].Data)
+	require.Empty(t, client.results["ok"].Error)
+	require.Equal(t, "ok", string(client.results["ok"].Data))
+}
+
+type mockClient struct {
+	retrievals map[string]*fleet.HostFileRetrieval
+	results    map[string]*fleet.HostFileRetrievalResultPayload
+	saveErr    error
+}
+
+func (m *mockClient) GetHostFileRetrieval(execID string) (*fleet.HostFileRetrieval, error) {
+	fr := m.retrievals[execID]
+	if fr == nil {
+		return nil, fmt.Errorf("no such file retrieval: %s", execID)
+	}
+	return fr, nil
+}
+
+func (m *mockClient) SaveHostFileRetrievalResult(result *fleet.HostFileRetrievalResultPayload) error {
+	if m.results == nil {
+		m.results = make(map[string]*fleet.HostFileRetrievalResultPayload)
+	}
+	m.results[result.ExecutionID] = result
+	return m.saveErr
+}
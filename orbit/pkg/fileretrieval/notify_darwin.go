@@ -0,0 +1,28 @@
+//go:build darwin
+
+package fileretrieval
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// notify displays a notification to the user logged in the console.
+func notify(message string) error {
+	out, err := exec.Command("/usr/bin/stat", "-f", "%u", "/dev/console").Output()
+	if err != nil {
+		return fmt.Errorf("get console user: %w", err)
+	}
+	uid := strings.TrimSpace(string(out))
+	if uid == "" || uid == "0" {
+		return fmt.Errorf("no user logged in")
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	script := fmt.Sprintf(`display notification "%s" with title "Fleet"`, escaper.Replace(message))
+	if out, err := exec.Command("/bin/launchctl", "asuser", uid, "/usr/bin/osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !windows
+
+package fileretrieval
+
+import "errors"
+
+// notify is not supported on this platform.
+func notify(message string) error {
+	return errors.New("end user notifications are not supported on this platform")
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package fileretrieval
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// notify displays a message to all users logged in the host.
+func notify(message string) error {
+	if out, err := exec.Command("msg.exe", "*", "/TIME:300", message).CombinedOutput(); err != nil {
+		return fmt.Errorf("msg.exe: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build darwin
+
+package logcollection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// collect exports the entries of the unified log that match the requested
+// predicate and time range as newline-delimited JSON.
+func collect(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+	if lc.Type != fleet.HostLogCollectionMacOSUnifiedLog {
+		return fmt.Errorf("unsupported log type on macOS: %s", lc.Type)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "unified_log.ndjson"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// log show interprets the start and end dates in the local time zone.
+	const logTime = "2006-01-02 15:04:05"
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/usr/bin/log", "show",
+		"--style", "ndjson",
+		"--info",
+		"--predicate", lc.Predicate,
+		"--start", lc.StartTime.Local().Format(logTime),
+		"--end", lc.EndTime.Local().Format(logTime),
+	)
+	// the output is limited so that a huge log cannot fill the disk, the
+	// command fails once the limit is reached.
+	cmd.Stdout = &limitedWriter{w: f, n: maxUncompressedLogSize}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, errLogsTooLarge) {
+			return err
+		}
+		return fmt.Errorf("log show: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return f.Close()
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package logcollection
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+func collect(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+	return errors.New("log collection is not supported on this platform")
+}
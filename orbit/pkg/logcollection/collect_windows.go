@@ -0,0 +1,37 @@
+//go:build windows
+
+package logcollection
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// collect exports the requested Windows Event Log channels, filtered on the
+// requested time range, in one .evtx file per channel.
+func collect(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+	if lc.Type != fleet.HostLogCollectionWindowsEventLog {
+		return fmt.Errorf("unsupported log type on Windows: %s", lc.Type)
+	}
+
+	const xpathTime = "2006-01-02T15:04:05.000Z"
+	query := fmt.Sprintf("*[System[TimeCreated[@SystemTime>='%s' and @SystemTime<='%s']]]",
+		lc.StartTime.UTC().Format(xpathTime), lc.EndTime.UTC().Format(xpathTime))
+
+	// channel names may contain characters that are not valid in file names
+	// (e.g. "Microsoft-Windows-PowerShell/Operational").
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+	for i, channel := range lc.Channels {
+		out := filepath.Join(dir, fmt.Sprintf("%02d-%s.evtx", i+1, replacer.Replace(channel)))
+		cmd := exec.CommandContext(ctx, "wevtutil.exe", "export-log", channel, out, "/q:"+query, "/ow:true")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("export channel %q: %w: %s", channel, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package logcollection implements support to collect logs from the host
+// (Windows Event Log channels or macOS unified log entries) when requested by
+// the Fleet server.
+package logcollection
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// maxCollectionTime is the maximum time allowed to collect the logs of a
+// single request.
+const maxCollectionTime = 10 * time.Minute
+
+// maxUncompressedLogSize is the maximum size of the logs written to disk
+// before they are compressed. The logs compress well, but the collection
+// stops early instead of filling the disk with logs that would be rejected
+// once compressed.
+const maxUncompressedLogSize = 20 * fleet.MaxHostLogCollectionSize
+
+// errLogsTooLarge is returned when the collected logs exceed
+// maxUncompressedLogSize.
+var errLogsTooLarge = fmt.Errorf("collected logs are too large: more than %d bytes uncompressed, try a shorter time range", maxUncompressedLogSize)
+
+// limitedWriter writes to w until n bytes are written, after which it fails
+// with errLogsTooLarge.
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		n, err := l.w.Write(p[:l.n])
+		l.n -= int64(n)
+		if err != nil {
+			return n, err
+		}
+		return n, errLogsTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	GetHostLogCollection(execID string) (*fleet.HostLogCollection, error)
+	SaveHostLogCollectionResult(result *fleet.HostLogCollectionResultPayload) error
+}
+
+// Collector is the type that processes log collection requests, taking care
+// of retrieving each request, collecting the logs in a temporary directory,
+// compressing them and uploading the result.
+type Collector struct {
+	Client Client
+	// Enabled indicates if this agent allows log collection. As collecting
+	// logs executes commands on the host, it follows the scripts execution
+	// setting. If it is false, an error result is sent for each request so
+	// that the Fleet server knows the agent processed it.
+	Enabled bool
+
+	// tempDirFn is the function to call to get the temporary directory to use,
+	// inside of which the request-specific subdirectories will be created. If
+	// nil, the user's temp dir will be used (can be set to t.TempDir in tests).
+	tempDirFn func() string
+
+	// collectFn can be set for tests to mock the actual collection of the logs
+	// in the provided directory. If nil, collect will be used, which has a
+	// different implementation on each platform.
+	collectFn func(ctx context.Context, lc *fleet.HostLogCollection, dir string) error
+}
+
+// Run processes all log collection requests identified by the execution IDs.
+func (c *Collector) Run(execIDs []string) error {
+	var errs []error
+	for _, execID := range execIDs {
+		if !c.Enabled {
+			if err := c.saveResult(&fleet.HostLogCollectionResultPayload{
+				ExecutionID: execID,
+				Error:       "Log collection is disabled. To collect logs, deploy the fleetd agent with --enable-scripts.",
+			}); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		lc, err := c.Client.GetHostLogCollection(execID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get host log collection: %w", err))
+			continue
+		}
+		if err := c.runOne(lc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (c *Collector) runOne(lc *fleet.HostLogCollection) error {
+	if lc.Status != fleet.HostLogCollectionStatusPending {
+		// already a result stored for this request, skip, it shouldn't be sent
+		// again by Fleet.
+		return nil
+	}
+
+	tempDir := os.TempDir()
+	if c.tempDirFn != nil {
+		tempDir = c.tempDirFn()
+	}
+	dir, err := os.MkdirTemp(tempDir, "fleet-logs-"+lc.ExecutionID+"-")
+	if err != nil {
+		return fmt.Errorf("create logs directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxCollectionTime)
+	defer cancel()
+
+	collectFn := c.collectFn
+	if collectFn == nil {
+		collectFn = collect
+	}
+
+	result := &fleet.HostLogCollectionResultPayload{ExecutionID: lc.ExecutionID}
+	if err := collectFn(ctx, lc, dir); err != nil {
+		result.Error = fmt.Sprintf("collect logs: %v", err)
+	} else {
+		data, err := archiveDir(dir)
+		switch {
+		case err != nil:
+			result.Error = fmt.Sprintf("compress logs: %v", err)
+		case len(data) > fleet.MaxHostLogCollectionSize:
+			result.Error = fmt.Sprintf("collected logs are too large: %d bytes compressed (maximum is %d bytes), try a shorter time range", len(data), fleet.MaxHostLogCollectionSize)
+		default:
+			result.Data = data
+		}
+	}
+	return c.saveResult(result)
+}
+
+func (c *Collector) saveResult(result *fleet.HostLogCollectionResultPayload) error {
+	if err := c.Client.SaveHostLogCollectionResult(result); err != nil {
+		return fmt.Errorf("save log collection result: %w", err)
+	}
+	return nil
+}
+
+// archiveDir returns a gzip-compressed tar archive of the regular files in
+// dir (not recursive).
+func archiveDir(dir string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := addFile(tw, filepath.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func addFile(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
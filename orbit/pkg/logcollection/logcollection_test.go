@@ -0,0 +1,222 @@
+package logcollection
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	pending := func() *fleet.HostLogCollection {
+		return &fleet.HostLogCollection{Status: fleet.HostLogCollectionStatusPending, Type: fleet.HostLogCollectionMacOSUnifiedLog}
+	}
+
+	cases := []struct {
+		desc      string
+		client    *mockClient
+		enabled   bool
+		collectFn func(ctx context.Context, lc *fleet.HostLogCollection, dir string) error
+		execIDs   []string
+
+		errContains     string
+		wantResults     []string
+		wantResultError map[string]string
+	}{
+		{
+			desc:    "no exec ids",
+			client:  &mockClient{},
+			enabled: true,
+		},
+		{
+			desc:        "success",
+			client:      &mockClient{collections: map[string]*fleet.HostLogCollection{"a": pending(), "b": pending()}},
+			enabled:     true,
+			execIDs:     []string{"a", "b"},
+			wantResults: []string{"a", "b"},
+		},
+		{
+			desc:            "disabled",
+			client:          &mockClient{},
+			enabled:         false,
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "Log collection is disabled"},
+		},
+		{
+			desc:        "one unknown",
+			client:      &mockClient{collections: map[string]*fleet.HostLogCollection{"a": pending()}},
+			enabled:     true,
+			execIDs:     []string{"b", "a"},
+			errContains: "no such log collection: b",
+			wantResults: []string{"a"},
+		},
+		{
+			desc: "already completed",
+			client: &mockClient{collections: map[string]*fleet.HostLogCollection{
+				"a": {Status: fleet.HostLogCollectionStatusCompleted},
+			}},
+			enabled: true,
+			execIDs: []string{"a"},
+		},
+		{
+			desc:    "collection fails",
+			client:  &mockClient{collections: map[string]*fleet.HostLogCollection{"a": pending()}},
+			enabled: true,
+			collectFn: func(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+				return errors.New("access denied")
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "collect logs: access denied"},
+		},
+		{
+			desc:    "save fails",
+			client:  &mockClient{collections: map[string]*fleet.HostLogCollection{"a": pending()}, saveErr: io.ErrUnexpectedEOF},
+			enabled: true,
+			execIDs: []string{"a"},
+
+			errContains: "save log collection result: unexpected EOF",
+			wantResults: []string{"a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			collectFn := c.collectFn
+			if collectFn == nil {
+				collectFn = func(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+					return os.WriteFile(filepath.Join(dir, "logs.txt"), []byte("logs of "+lc.ExecutionID), 0o600)
+				}
+			}
+			for id, lc := range c.client.collections {
+				lc.ExecutionID = id
+			}
+
+			tempDir := t.TempDir()
+			collector := &Collector{
+				Client:    c.client,
+				Enabled:   c.enabled,
+				tempDirFn: func() string { return tempDir },
+				collectFn: collectFn,
+			}
+			err := collector.Run(c.execIDs)
+			if c.errContains != "" {
+				require.ErrorContains(t, err, c.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, c.client.results, len(c.wantResults))
+			for _, id := range c.wantResults {
+				res := c.client.results[id]
+				require.NotNil(t, res, id)
+				if msg := c.wantResultError[id]; msg != "" {
+					require.Contains(t, res.Error, msg)
+					require.Empty(t, res.Data)
+					continue
+				}
+				require.Empty(t, res.Error)
+				require.Equal(t, map[string]string{"logs.txt": "logs of " + id}, readArchive(t, res.Data))
+			}
+
+			// the temporary directories are removed
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestCollectorTooLarge(t *testing.T) {
+	client := &mockClient{collections: map[string]*fleet.HostLogCollection{
+		"a": {ExecutionID: "a", Status: fleet.HostLogCollectionStatusPending},
+	}}
+	collector := &Collector{
+		Client:    client,
+		Enabled:   true,
+		tempDirFn: t.TempDir,
+		collectFn: func(ctx context.Context, lc *fleet.HostLogCollection, dir string) error {
+			// random bytes do not compress
+			f, err := os.Create(filepath.Join(dir, "big.bin"))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.CopyN(f, rand.New(rand.NewSource(1)), fleet.MaxHostLogCollectionSize+1) //nolint:gosec // test data only
+			return err
+		},
+	}
+	require.NoError(t, collector.Run([]string{"a"}))
+	require.Contains(t, client.results["a"].Error, "collected logs are too large")
+	require.Empty(t, client.results["a"].Data)
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &limitedWriter{w: &buf, n: 5}
+
+	n, err := w.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	n, err = w.Write([]byte("defg"))
+	require.ErrorIs(t, err, errLogsTooLarge)
+	require.Equal(t, 2, n)
+	require.Equal(t, "abcde", buf.String())
+
+	n, err = w.Write([]byte("h"))
+	require.ErrorIs(t, err, errLogsTooLarge)
+	require.Zero(t, n)
+	require.Equal(t, "abcde", buf.String())
+}
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(b)
+	}
+	return files
+}
+
+type mockClient struct {
+	collections map[string]*fleet.HostLogCollection
+	results     map[string]*fleet.HostLogCollectionResultPayload
+	saveErr     error
+}
+
+func (m *mockClient) GetHostLogCollection(execID string) (*fleet.HostLogCollection, error) {
+	lc := m.collections[execID]
+	if lc == nil {
+		return nil, fmt.Errorf("no such log collection: %s", execID)
+	}
+	return lc, nil
+}
+
+func (m *mockClient) SaveHostLogCollectionResult(result *fleet.HostLogCollectionResultPayload) error {
+	if m.results == nil {
+		m.results = make(map[string]*fleet.HostLogCollectionResultPayload)
+	}
+	m.results[result.ExecutionID] = result
+	return m.saveErr
+}
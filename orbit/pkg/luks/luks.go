@@ -0,0 +1,76 @@
+// Package luks manages the passphrases of the LUKS encrypted root volume of
+// Linux hosts, so that a passphrase known to Fleet can be escrowed.
+package luks
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// passphraseLength is the length of the passphrases generated for Fleet.
+const passphraseLength = 32
+
+// passphraseAlphabet excludes characters that are easily confused when the
+// passphrase is typed at boot.
+const passphraseAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+// ErrPromptCancelled is returned when the user cancels the passphrase prompt.
+var ErrPromptCancelled = errors.New("passphrase prompt was cancelled by the user")
+
+// ErrRootNotEncrypted is returned when the root filesystem is not on a LUKS
+// encrypted volume.
+var ErrRootNotEncrypted = errors.New("root filesystem is not on a LUKS encrypted volume")
+
+// GeneratePassphrase returns a new random passphrase.
+func GeneratePassphrase() (string, error) {
+	max := big.NewInt(int64(len(passphraseAlphabet)))
+	var sb strings.Builder
+	for i := 0; i < passphraseLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("generate passphrase: %w", err)
+		}
+		sb.WriteByte(passphraseAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// parseLsblkOutput returns the LUKS device from the output of
+// `lsblk -s -p -n -l -o NAME,TYPE <root source>`, which lists the root
+// filesystem device followed by its ancestors. The LUKS device is the parent
+// of the first "crypt" (dm-crypt mapping) device.
+func parseLsblkOutput(out string) (string, error) {
+	foundCrypt := false
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if foundCrypt {
+			return fields[0], nil
+		}
+		foundCrypt = fields[1] == "crypt"
+	}
+	return "", ErrRootNotEncrypted
+}
+
+var keySlotUnlockedRx = regexp.MustCompile(`(?m)^Key slot (\d+) unlocked`)
+
+// parseKeySlot returns the key slot from the verbose output of
+// `cryptsetup open --test-passphrase`.
+func parseKeySlot(out string) (uint, error) {
+	m := keySlotUnlockedRx.FindStringSubmatch(out)
+	if m == nil {
+		return 0, errors.New("no key slot unlocked by the passphrase")
+	}
+	slot, err := strconv.ParseUint(m[1], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("parse key slot: %w", err)
+	}
+	return uint(slot), nil
+}
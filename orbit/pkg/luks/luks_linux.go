@@ -0,0 +1,134 @@
+package luks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/execuser"
+)
+
+// RootDevice returns the path of the LUKS device that backs the root
+// filesystem.
+func RootDevice() (string, error) {
+	source, err := exec.Command("findmnt", "-n", "-o", "SOURCE", "/").Output()
+	if err != nil {
+		return "", fmt.Errorf("find root filesystem source: %w", err)
+	}
+	out, err := exec.Command("lsblk", "-s", "-p", "-n", "-l", "-o", "NAME,TYPE", strings.TrimSpace(string(source))).Output()
+	if err != nil {
+		return "", fmt.Errorf("list root filesystem devices: %w", err)
+	}
+	return parseLsblkOutput(string(out))
+}
+
+// AddKey adds newPassphrase to a free key slot of the LUKS device, using
+// passphrase to unlock it, and returns the key slot it was added to.
+func AddKey(device, passphrase, newPassphrase string) (uint, error) {
+	// the new passphrase is read from a file so that it never appears in the
+	// process arguments.
+	f, err := os.CreateTemp("", "fleet-luks-*")
+	if err != nil {
+		return 0, fmt.Errorf("create key file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(newPassphrase); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("close key file: %w", err)
+	}
+
+	if err := runCryptsetup(passphrase, "luksAddKey", "--key-file=-", device, f.Name()); err != nil {
+		return 0, fmt.Errorf("add key: %w", err)
+	}
+	return KeySlot(device, newPassphrase)
+}
+
+// KeySlot returns the key slot of the LUKS device unlocked by passphrase.
+func KeySlot(device, passphrase string) (uint, error) {
+	cmd := exec.Command("cryptsetup", "open", "--test-passphrase", "--key-file=-", "-v", device)
+	cmd.Stdin = strings.NewReader(passphrase)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("test passphrase: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return parseKeySlot(string(out))
+}
+
+// KillSlot removes the key slot of the LUKS device, using passphrase (which
+// must unlock another slot) to unlock it.
+func KillSlot(device string, slot uint, passphrase string) error {
+	if err := runCryptsetup(passphrase, "luksKillSlot", "--key-file=-", device, strconv.FormatUint(uint64(slot), 10)); err != nil {
+		return fmt.Errorf("kill key slot %d: %w", slot, err)
+	}
+	return nil
+}
+
+// PromptPassphrase asks the logged in user for a current passphrase of the
+// encrypted disk. It returns ErrPromptCancelled if the user cancels.
+func PromptPassphrase() (string, error) {
+	zenity, err := exec.LookPath("zenity")
+	if err != nil {
+		return "", fmt.Errorf("zenity is required to prompt for the disk passphrase: %w", err)
+	}
+	out, exitCode, err := execuser.RunWithOutput(zenity,
+		execuser.WithArg("--entry", ""),
+		execuser.WithArg("--hide-text", ""),
+		execuser.WithArg("--title", "Fleet"),
+		execuser.WithArg("--text", "Your organization requires Fleet to escrow the disk encryption key of this device. Enter your current disk encryption passphrase:"),
+	)
+	switch {
+	case err != nil:
+		return "", fmt.Errorf("prompt passphrase: %w", err)
+	case exitCode != 0:
+		// zenity exits with 1 when the dialog is cancelled or closed
+		return "", ErrPromptCancelled
+	}
+	passphrase := strings.TrimRight(string(out), "\n")
+	if passphrase == "" {
+		return "", ErrPromptCancelled
+	}
+	return passphrase, nil
+}
+
+// encryptionGuidanceText explains to the user how to encrypt the root volume.
+// The root filesystem cannot be encrypted in place while it is mounted, so
+// the user must reinstall the system with disk encryption enabled (or convert
+// it offline, e.g. with `cryptsetup reencrypt --encrypt` from a live system).
+const encryptionGuidanceText = "Your organization requires the disk of this device to be encrypted, but it is not.\n\n" +
+	"The disk cannot be encrypted while the system is running. To encrypt it, back up your data and " +
+	"reinstall the operating system with disk encryption (LUKS) enabled, " +
+	"or contact your IT administrator for assistance.\n\n" +
+	"Once the disk is encrypted, you will be asked for its passphrase so that it can be escrowed with Fleet."
+
+// ShowEncryptionGuidance shows a dialog to the logged in user with the
+// instructions to encrypt the root volume.
+func ShowEncryptionGuidance() error {
+	zenity, err := exec.LookPath("zenity")
+	if err != nil {
+		return fmt.Errorf("zenity is required to show the disk encryption guidance: %w", err)
+	}
+	if _, _, err := execuser.RunWithOutput(zenity,
+		execuser.WithArg("--warning", ""),
+		execuser.WithArg("--no-wrap", ""),
+		execuser.WithArg("--title", "Fleet"),
+		execuser.WithArg("--text", encryptionGuidanceText),
+	); err != nil {
+		return fmt.Errorf("show disk encryption guidance: %w", err)
+	}
+	return nil
+}
+
+func runCryptsetup(passphrase string, args ...string) error {
+	cmd := exec.Command("cryptsetup", args...)
+	cmd.Stdin = strings.NewReader(passphrase)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cryptsetup %s: %w: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
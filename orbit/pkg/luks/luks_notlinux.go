@@ -0,0 +1,31 @@
+//go:build !linux
+
+package luks
+
+import "errors"
+
+var errNotSupported = errors.New("LUKS is only supported on Linux")
+
+func RootDevice() (string, error) {
+	return "", errNotSupported
+}
+
+func AddKey(device, passphrase, newPassphrase string) (uint, error) {
+	return 0, errNotSupported
+}
+
+func KeySlot(device, passphrase string) (uint, error) {
+	return 0, errNotSupported
+}
+
+func KillSlot(device string, slot uint, passphrase string) error {
+	return errNotSupported
+}
+
+func PromptPassphrase() (string, error) {
+	return "", errNotSupported
+}
+
+func ShowEncryptionGuidance() error {
+	return errNotSupported
+}
@@ -0,0 +1,78 @@
+package luks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassphrase(t *testing.T) {
+	p1, err := GeneratePassphrase()
+	require.NoError(t, err)
+	require.Len(t, p1, passphraseLength)
+	for _, c := range p1 {
+		require.True(t, strings.ContainsRune(passphraseAlphabet, c), string(c))
+	}
+
+	p2, err := GeneratePassphrase()
+	require.NoError(t, err)
+	require.NotEqual(t, p1, p2)
+}
+
+func TestParseLsblkOutput(t *testing.T) {
+	cases := []struct {
+		name    string
+		out     string
+		device  string
+		wantErr bool
+	}{
+		{
+			name: "lvm on luks",
+			out: `/dev/mapper/ubuntu--vg-ubuntu--lv lvm
+/dev/mapper/dm_crypt-0            crypt
+/dev/nvme0n1p3                    part
+/dev/nvme0n1                      disk
+`,
+			device: "/dev/nvme0n1p3",
+		},
+		{
+			name: "luks on partition",
+			out: `/dev/mapper/luks-1234 crypt
+/dev/sda2             part
+/dev/sda              disk`,
+			device: "/dev/sda2",
+		},
+		{
+			name: "not encrypted",
+			out: `/dev/sda2 part
+/dev/sda  disk`,
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			out:     "",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			device, err := parseLsblkOutput(c.out)
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.device, device)
+		})
+	}
+}
+
+func TestParseKeySlot(t *testing.T) {
+	slot, err := parseKeySlot("Key slot 3 unlocked.\nCommand successful.\n")
+	require.NoError(t, err)
+	require.Equal(t, uint(3), slot)
+
+	_, err = parseKeySlot("No key available with this passphrase.\n")
+	require.Error(t, err)
+}
@@ -189,6 +189,12 @@ func (r *Runner) Interrupt(err error) {
 }
 
 func (r *Runner) ExtensionSocketPath() string {
+	return ExtensionSocketPath(r.dataPath)
+}
+
+// ExtensionSocketPath returns the path of the extension manager socket of the
+// osqueryd process run by orbit with the provided data path.
+func ExtensionSocketPath(dataPath string) string {
 	const (
 		extensionSocketName        = "orbit-osquery.em"
 		windowsExtensionSocketPath = `\\.\pipe\orbit-osquery-extension`
@@ -196,7 +202,7 @@ func (r *Runner) ExtensionSocketPath() string {
 	if runtime.GOOS == "windows" {
 		return windowsExtensionSocketPath
 	}
-	return filepath.Join(r.dataPath, extensionSocketName)
+	return filepath.Join(dataPath, extensionSocketName)
 }
 
 func (r *Runner) setCancel(c func()) {
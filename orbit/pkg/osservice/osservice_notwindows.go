@@ -3,7 +3,9 @@
 
 package osservice
 
+import "github.com/fleetdm/fleet/v4/orbit/pkg/powerevents"
+
 // SetupServiceManagement is currently a placeholder for non-windows OSes
 // system service configuration
-func SetupServiceManagement(serviceName string, interruptCh chan struct{}, doneCh chan struct{}) {
+func SetupServiceManagement(serviceName string, interruptCh chan struct{}, doneCh chan struct{}, powerHooks *powerevents.Hooks) {
 }
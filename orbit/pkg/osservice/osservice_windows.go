@@ -6,19 +6,37 @@ package osservice
 import (
 	"os"
 
+	"github.com/fleetdm/fleet/v4/orbit/pkg/powerevents"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
 )
 
+// pbtAPMSuspend is the power event type notified when the computer is about
+// to enter a suspended state, see
+// https://learn.microsoft.com/en-us/windows/win32/power/pbt-apmsuspend
+const pbtAPMSuspend = 0x4
+
 type windowsService struct {
 	interruptCh chan struct{}
 	appDoneCh   chan struct{}
+	powerHooks  *powerevents.Hooks
+}
+
+// runPowerHooks runs the power-event hooks for the event, if any.
+func (m *windowsService) runPowerHooks(event powerevents.Event) {
+	if m.powerHooks == nil {
+		return
+	}
+	log.Info().Msgf("running %s hooks", event)
+	if err := m.powerHooks.Run(event); err != nil {
+		log.Error().Err(err).Msgf("%s hooks", event)
+	}
 }
 
 func (m *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
 	// Accepted service operations
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown
+	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPreShutdown | svc.AcceptPowerEvent
 
 	// Expected service status update during initialization
 	changes <- svc.Status{State: svc.StartPending}
@@ -32,7 +50,18 @@ func (m *windowsService) Execute(args []string, requests <-chan svc.ChangeReques
 			log.Info().Msg("Service Interrogate Requested")
 			changes <- req.CurrentStatus
 
-		case svc.Stop, svc.Shutdown:
+		case svc.PowerEvent:
+			if req.EventType == pbtAPMSuspend {
+				m.runPowerHooks(powerevents.Sleep)
+			}
+
+		case svc.PreShutdown, svc.Stop, svc.Shutdown:
+			if req.Cmd == svc.PreShutdown {
+				// the pre-shutdown notification gives more time to the service to
+				// run the hooks than the shutdown one.
+				m.runPowerHooks(powerevents.Shutdown)
+			}
+
 			log.Info().Msg("Service Stop Requested")
 
 			// Updating the service state to indicate stop
@@ -58,8 +87,9 @@ func (m *windowsService) Execute(args []string, requests <-chan svc.ChangeReques
 }
 
 // SetupServiceManagement implements the dispatcher and notification logic to
-// interact with the Windows Service Control Manager (SCM)
-func SetupServiceManagement(serviceName string, interruptCh chan struct{}, doneCh chan struct{}) {
+// interact with the Windows Service Control Manager (SCM). The powerHooks, if
+// not nil, are run when the host is about to go to sleep or shut down.
+func SetupServiceManagement(serviceName string, interruptCh chan struct{}, doneCh chan struct{}, powerHooks *powerevents.Hooks) {
 	if serviceName == "" {
 		log.Error().Msg(" service name should not be empty")
 		return
@@ -76,6 +106,7 @@ func SetupServiceManagement(serviceName string, interruptCh chan struct{}, doneC
 		srvData := windowsService{
 			interruptCh: interruptCh,
 			appDoneCh:   doneCh,
+			powerHooks:  powerHooks,
 		}
 
 		// Registering our service into the SCM
@@ -0,0 +1,107 @@
+//go:build linux
+// +build linux
+
+package powerevents
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	logindDest      = "org.freedesktop.login1"
+	logindPath      = dbus.ObjectPath("/org/freedesktop/login1")
+	logindInterface = "org.freedesktop.login1.Manager"
+)
+
+// Listen runs the hooks when systemd-logind signals that the host is about
+// to go to sleep or shut down, until ctx is done. It holds a "delay"
+// inhibitor lock so that logind waits for the hooks to complete (up to its
+// InhibitDelayMaxSec setting) before proceeding.
+func Listen(ctx context.Context, hooks *Hooks) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connect to system bus: %w", err)
+	}
+	defer conn.Close()
+
+	for _, member := range []string{"PrepareForSleep", "PrepareForShutdown"} {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchObjectPath(logindPath),
+			dbus.WithMatchInterface(logindInterface),
+			dbus.WithMatchMember(member),
+		); err != nil {
+			return fmt.Errorf("add match for %s: %w", member, err)
+		}
+	}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	lock, err := inhibit(conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if lock != nil {
+			lock.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case sig := <-signals:
+			if sig == nil || len(sig.Body) == 0 {
+				continue
+			}
+			// the signals are sent with true before the event, and with false
+			// after it (i.e. on resume, for PrepareForSleep).
+			start, ok := sig.Body[0].(bool)
+			if !ok {
+				continue
+			}
+			if !start {
+				if lock == nil {
+					if lock, err = inhibit(conn); err != nil {
+						log.Error().Err(err).Msg("power events: take inhibitor lock after resume")
+					}
+				}
+				continue
+			}
+
+			event := Sleep
+			if sig.Name == logindInterface+".PrepareForShutdown" {
+				event = Shutdown
+			}
+			log.Info().Msgf("power events: running %s hooks", event)
+			if err := hooks.Run(event); err != nil {
+				log.Error().Err(err).Msgf("power events: %s hooks", event)
+			}
+			// release the lock so that logind can proceed
+			if lock != nil {
+				lock.Close()
+				lock = nil
+			}
+		}
+	}
+}
+
+// inhibit takes a logind "delay" inhibitor lock for sleep and shutdown, which
+// is held until the returned file is closed.
+func inhibit(conn *dbus.Conn) (*os.File, error) {
+	var fd dbus.UnixFD
+	if err := conn.Object(logindDest, logindPath).Call(
+		logindInterface+".Inhibit", 0,
+		"sleep:shutdown", "fleetd", "Flush pending script results", "delay",
+	).Store(&fd); err != nil {
+		return nil, fmt.Errorf("take inhibitor lock: %w", err)
+	}
+	return os.NewFile(uintptr(fd), "logind-inhibitor"), nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package powerevents
+
+import "context"
+
+// Listen blocks until ctx is done. On Windows, the power events are received
+// by the service control handler (see the osservice package), and on macOS
+// being notified of sleep requires IOKit (and thus cgo), which orbit is not
+// built with. On those platforms, the Shutdown hooks are run when orbit
+// stops, which is the case when the host shuts down.
+func Listen(ctx context.Context, hooks *Hooks) error {
+	<-ctx.Done()
+	return nil
+}
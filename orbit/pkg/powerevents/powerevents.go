@@ -0,0 +1,91 @@
+// Package powerevents allows orbit to run hooks when the host is about to go
+// to sleep or shut down, so that pending work (e.g. script results) can be
+// flushed and persisted before it happens.
+package powerevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event is a power event of the host.
+type Event string
+
+const (
+	// Sleep is the event of the host going to sleep (e.g. lid closed).
+	Sleep Event = "sleep"
+	// Shutdown is the event of the host shutting down or orbit stopping.
+	Shutdown Event = "shutdown"
+)
+
+// DefaultTimeout is the default maximum duration given to the hooks to run
+// for a single event.
+const DefaultTimeout = 10 * time.Second
+
+// HookFunc is a function called when a power event occurs. It must return
+// promptly once ctx is done.
+type HookFunc func(ctx context.Context, event Event) error
+
+type hook struct {
+	name string
+	fn   HookFunc
+}
+
+// Hooks is the registry of the hooks to call on power events. The zero value
+// is ready to use.
+type Hooks struct {
+	// Timeout is the maximum duration given to all hooks to run for an event,
+	// DefaultTimeout is used if it is <= 0.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	hooks []hook
+}
+
+// Register adds a hook identified by name, hooks are called in the order
+// they were registered.
+func (h *Hooks) Register(name string, fn HookFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook{name: name, fn: fn})
+}
+
+// Run calls the registered hooks for the event. It returns once all hooks
+// have returned or when the timeout expires, whichever comes first, and
+// returns the errors of the hooks that failed or did not complete in time.
+func (h *Hooks) Run(event Event) error {
+	h.mu.Lock()
+	hooks := make([]hook, len(h.hooks))
+	copy(hooks, h.hooks)
+	h.mu.Unlock()
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, hk := range hooks {
+		done := make(chan error, 1)
+		go func(fn HookFunc) {
+			done <- fn(ctx, event)
+		}(hk.fn)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s hook %s: %w", event, hk.name, err))
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("%s hook %s: %w", event, hk.name, ctx.Err()))
+			// no time left for the remaining hooks
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,60 @@
+package powerevents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooksRun(t *testing.T) {
+	t.Run("no hooks", func(t *testing.T) {
+		var h Hooks
+		require.NoError(t, h.Run(Sleep))
+	})
+
+	t.Run("hooks run in order", func(t *testing.T) {
+		var h Hooks
+		var calls []string
+		h.Register("a", func(ctx context.Context, event Event) error {
+			calls = append(calls, "a:"+string(event))
+			return nil
+		})
+		h.Register("b", func(ctx context.Context, event Event) error {
+			calls = append(calls, "b:"+string(event))
+			return errors.New("b failed")
+		})
+		h.Register("c", func(ctx context.Context, event Event) error {
+			calls = append(calls, "c:"+string(event))
+			return nil
+		})
+
+		err := h.Run(Shutdown)
+		require.ErrorContains(t, err, "shutdown hook b: b failed")
+		require.Equal(t, []string{"a:shutdown", "b:shutdown", "c:shutdown"}, calls)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		h := Hooks{Timeout: 50 * time.Millisecond}
+		block := make(chan struct{})
+		defer close(block)
+		var called bool
+		h.Register("slow", func(ctx context.Context, event Event) error {
+			<-block
+			return nil
+		})
+		h.Register("never", func(ctx context.Context, event Event) error {
+			called = true
+			return nil
+		})
+
+		start := time.Now()
+		err := h.Run(Sleep)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorContains(t, err, "sleep hook slow")
+		require.Less(t, time.Since(start), time.Second)
+		require.False(t, called)
+	})
+}
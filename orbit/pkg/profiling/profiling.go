@@ -0,0 +1,117 @@
+// Package profiling implements support to capture pprof profiles of the
+// orbit process when requested by the Fleet server, so that performance
+// issues of the agent can be diagnosed remotely.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	GetHostPprofCapture(execID string) (*fleet.HostPprofCapture, error)
+	SaveHostPprofCaptureResult(result *fleet.HostPprofCaptureResultPayload) error
+}
+
+// Capturer is the type that processes pprof capture requests, taking care of
+// retrieving each request, capturing the profile and uploading it.
+type Capturer struct {
+	Client Client
+
+	// captureFn can be set for tests to mock the capture of the profile. If
+	// nil, capture will be used.
+	captureFn func(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error)
+}
+
+// Run processes all pprof capture requests identified by the execution IDs.
+func (c *Capturer) Run(execIDs []string) error {
+	var errs []error
+	for _, execID := range execIDs {
+		pc, err := c.Client.GetHostPprofCapture(execID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get host pprof capture: %w", err))
+			continue
+		}
+		if err := c.runOne(pc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (c *Capturer) runOne(pc *fleet.HostPprofCapture) error {
+	if pc.Status != fleet.HostPprofCaptureStatusPending {
+		// already a result stored for this request, skip, it shouldn't be sent
+		// again by Fleet.
+		return nil
+	}
+
+	captureFn := c.captureFn
+	if captureFn == nil {
+		captureFn = capture
+	}
+	// the CPU profile takes the requested duration, give some leeway for the
+	// other types and for writing the profile.
+	ctx, cancel := context.WithTimeout(context.Background(), pc.Duration()+time.Minute)
+	defer cancel()
+
+	result := &fleet.HostPprofCaptureResultPayload{ExecutionID: pc.ExecutionID}
+	data, err := captureFn(ctx, pc)
+	switch {
+	case err != nil:
+		result.Error = fmt.Sprintf("capture %s profile: %s", pc.Type, err)
+	case len(data) > fleet.MaxHostPprofCaptureSize:
+		result.Error = fmt.Sprintf("profile is too large: %d bytes (maximum is %d bytes)", len(data), fleet.MaxHostPprofCaptureSize)
+	default:
+		result.Data = data
+	}
+
+	if err := c.Client.SaveHostPprofCaptureResult(result); err != nil {
+		return fmt.Errorf("save pprof capture result: %w", err)
+	}
+	return nil
+}
+
+// capture returns the requested profile of the current process in the
+// gzip-compressed protobuf format expected by "go tool pprof".
+func capture(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error) {
+	var buf bytes.Buffer
+	switch pc.Type {
+	case fleet.HostPprofProfileCPU:
+		// this fails if a CPU profile is already running, e.g. from another
+		// request.
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(pc.Duration()):
+		case <-ctx.Done():
+		}
+		pprof.StopCPUProfile()
+
+	case fleet.HostPprofProfileHeap, fleet.HostPprofProfileGoroutine:
+		p := pprof.Lookup(string(pc.Type))
+		if p == nil {
+			return nil, fmt.Errorf("unknown profile: %s", pc.Type)
+		}
+		if err := p.WriteTo(&buf, 0); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported profile type: %s", pc.Type)
+	}
+	return buf.Bytes(), nil
+}
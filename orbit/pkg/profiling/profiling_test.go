@@ -0,0 +1,164 @@
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapturer(t *testing.T) {
+	pending := func(typ fleet.HostPprofProfileType) *fleet.HostPprofCapture {
+		return &fleet.HostPprofCapture{Status: fleet.HostPprofCaptureStatusPending, Type: typ}
+	}
+
+	cases := []struct {
+		desc      string
+		client    *mockClient
+		captureFn func(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error)
+		execIDs   []string
+
+		errContains     string
+		wantResults     []string
+		wantResultError map[string]string
+	}{
+		{
+			desc:   "no exec ids",
+			client: &mockClient{},
+		},
+		{
+			desc: "success",
+			client: &mockClient{captures: map[string]*fleet.HostPprofCapture{
+				"a": pending(fleet.HostPprofProfileHeap),
+				"b": pending(fleet.HostPprofProfileGoroutine),
+			}},
+			execIDs:     []string{"a", "b"},
+			wantResults: []string{"a", "b"},
+		},
+		{
+			desc:        "one unknown",
+			client:      &mockClient{captures: map[string]*fleet.HostPprofCapture{"a": pending(fleet.HostPprofProfileHeap)}},
+			execIDs:     []string{"b", "a"},
+			errContains: "no such pprof capture: b",
+			wantResults: []string{"a"},
+		},
+		{
+			desc: "already completed",
+			client: &mockClient{captures: map[string]*fleet.HostPprofCapture{
+				"a": {Status: fleet.HostPprofCaptureStatusCompleted, Type: fleet.HostPprofProfileHeap},
+			}},
+			execIDs: []string{"a"},
+		},
+		{
+			desc:   "capture fails",
+			client: &mockClient{captures: map[string]*fleet.HostPprofCapture{"a": pending(fleet.HostPprofProfileCPU)}},
+			captureFn: func(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error) {
+				return nil, errors.New("cpu profiling already in use")
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "capture cpu profile: cpu profiling already in use"},
+		},
+		{
+			desc:   "profile too large",
+			client: &mockClient{captures: map[string]*fleet.HostPprofCapture{"a": pending(fleet.HostPprofProfileHeap)}},
+			captureFn: func(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error) {
+				return make([]byte, fleet.MaxHostPprofCaptureSize+1), nil
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "profile is too large"},
+		},
+		{
+			desc:    "save fails",
+			client:  &mockClient{captures: map[string]*fleet.HostPprofCapture{"a": pending(fleet.HostPprofProfileHeap)}, saveErr: io.ErrUnexpectedEOF},
+			execIDs: []string{"a"},
+
+			errContains: "save pprof capture result: unexpected EOF",
+			wantResults: []string{"a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			captureFn := c.captureFn
+			if captureFn == nil {
+				captureFn = func(ctx context.Context, pc *fleet.HostPprofCapture) ([]byte, error) {
+					return []byte(fmt.Sprintf("%s profile of %s", pc.Type, pc.ExecutionID)), nil
+				}
+			}
+			for id, pc := range c.client.captures {
+				pc.ExecutionID = id
+			}
+
+			capturer := &Capturer{Client: c.client, captureFn: captureFn}
+			err := capturer.Run(c.execIDs)
+			if c.errContains != "" {
+				require.ErrorContains(t, err, c.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, c.client.results, len(c.wantResults))
+			for _, id := range c.wantResults {
+				res := c.client.results[id]
+				require.NotNil(t, res, id)
+				if msg := c.wantResultError[id]; msg != "" {
+					require.Contains(t, res.Error, msg)
+					require.Empty(t, res.Data)
+					continue
+				}
+				require.Empty(t, res.Error)
+				pc := c.client.captures[id]
+				require.Equal(t, fmt.Sprintf("%s profile of %s", pc.Type, id), string(res.Data))
+			}
+		})
+	}
+}
+
+func TestCapture(t *testing.T) {
+	ctx := context.Background()
+
+	for _, typ := range []fleet.HostPprofProfileType{fleet.HostPprofProfileHeap, fleet.HostPprofProfileGoroutine} {
+		data, err := capture(ctx, &fleet.HostPprofCapture{Type: typ})
+		require.NoError(t, err)
+		// profiles are gzip-compressed
+		require.Greater(t, len(data), 2)
+		require.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+	}
+
+	// the cpu profile stops when the context is done
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	data, err := capture(cctx, &fleet.HostPprofCapture{Type: fleet.HostPprofProfileCPU, DurationSeconds: 60})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+
+	_, err = capture(ctx, &fleet.HostPprofCapture{Type: "mutex"})
+	require.ErrorContains(t, err, "unsupported profile type")
+}
+
+type mockClient struct {
+	captures map[string]*fleet.HostPprofCapture
+	results  map[string]*fleet.HostPprofCaptureResultPayload
+	saveErr  error
+}
+
+func (m *mockClient) GetHostPprofCapture(execID string) (*fleet.HostPprofCapture, error) {
+	pc := m.captures[execID]
+	if pc == nil {
+		return nil, fmt.Errorf("no such pprof capture: %s", execID)
+	}
+	return pc, nil
+}
+
+func (m *mockClient) SaveHostPprofCaptureResult(result *fleet.HostPprofCaptureResultPayload) error {
+	if m.results == nil {
+		m.results = make(map[string]*fleet.HostPprofCaptureResultPayload)
+	}
+	m.results[result.ExecutionID] = result
+	return m.saveErr
+}
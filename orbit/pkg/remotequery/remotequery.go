@@ -0,0 +1,121 @@
+// Package remotequery implements support to run one-off osquery queries
+// requested by the Fleet server outside of the distributed queries interval.
+// The queries are sent to osqueryd via the extension manager socket and the
+// results are sent back to Fleet as soon as they are available.
+package remotequery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/osquery/osquery-go"
+)
+
+// queryTimeout is the maximum time a query can take to run.
+const queryTimeout = time.Minute
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	GetHostRemoteQuery(execID string) (*fleet.HostRemoteQuery, error)
+	SaveHostRemoteQueryResult(result *fleet.HostRemoteQueryResultPayload) error
+}
+
+// Runner is the type that processes remote query requests, taking care of
+// retrieving each request, running the query and sending the results.
+type Runner struct {
+	Client Client
+	// ExtensionSocket is the path of osqueryd's extension manager socket.
+	ExtensionSocket string
+
+	// queryFn can be set for tests to mock the query to osquery. If nil, query
+	// will be used.
+	queryFn func(ctx context.Context, socket, sql string) ([]map[string]string, error)
+}
+
+// Run processes all remote query requests identified by the execution IDs.
+func (r *Runner) Run(execIDs []string) error {
+	var errs []error
+	for _, execID := range execIDs {
+		rq, err := r.Client.GetHostRemoteQuery(execID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get host remote query: %w", err))
+			continue
+		}
+		if err := r.runOne(rq); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (r *Runner) runOne(rq *fleet.HostRemoteQuery) error {
+	if rq.Status != fleet.HostRemoteQueryStatusPending {
+		// already a result stored for this request, skip, it shouldn't be sent
+		// again by Fleet.
+		return nil
+	}
+
+	queryFn := r.queryFn
+	if queryFn == nil {
+		queryFn = query
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	result := &fleet.HostRemoteQueryResultPayload{ExecutionID: rq.ExecutionID}
+	rows, err := queryFn(ctx, r.ExtensionSocket, rq.Query)
+	switch {
+	case err != nil:
+		result.Error = fmt.Sprintf("run query: %s", err)
+	case len(rows) > fleet.MaxHostRemoteQueryRows:
+		result.Error = fmt.Sprintf("too many rows: %d (maximum is %d)", len(rows), fleet.MaxHostRemoteQueryRows)
+	default:
+		if b, err := json.Marshal(rows); err == nil && len(b) > fleet.MaxHostRemoteQueryResultSize {
+			result.Error = fmt.Sprintf("results are too large: %d bytes (maximum is %d bytes)", len(b), fleet.MaxHostRemoteQueryResultSize)
+			break
+		}
+		result.Rows = rows
+	}
+
+	if err := r.Client.SaveHostRemoteQueryResult(result); err != nil {
+		return fmt.Errorf("save remote query result: %w", err)
+	}
+	return nil
+}
+
+// query runs the SQL statement on osqueryd via its extension manager socket.
+func query(ctx context.Context, socket, sql string) ([]map[string]string, error) {
+	client, err := osquery.NewClient(socket, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connect to osquery extension socket: %w", err)
+	}
+	defer client.Close()
+
+	type queryResult struct {
+		rows []map[string]string
+		err  error
+	}
+	// the thrift client does not support cancellation, the connection is closed
+	// by the deferred Close if the context is done first.
+	done := make(chan queryResult, 1)
+	go func() {
+		rows, err := client.QueryRows(sql)
+		done <- queryResult{rows: rows, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.rows, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
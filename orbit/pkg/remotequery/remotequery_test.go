@@ -0,0 +1,153 @@
+package remotequery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner(t *testing.T) {
+	pending := func(sql string) *fleet.HostRemoteQuery {
+		return &fleet.HostRemoteQuery{Status: fleet.HostRemoteQueryStatusPending, Query: sql}
+	}
+
+	cases := []struct {
+		desc    string
+		client  *mockClient
+		queryFn func(ctx context.Context, socket, sql string) ([]map[string]string, error)
+		execIDs []string
+
+		errContains     string
+		wantResults     []string
+		wantResultError map[string]string
+	}{
+		{
+			desc:   "no exec ids",
+			client: &mockClient{},
+		},
+		{
+			desc: "success",
+			client: &mockClient{queries: map[string]*fleet.HostRemoteQuery{
+				"a": pending("SELECT 1"),
+				"b": pending("SELECT 2"),
+			}},
+			execIDs:     []string{"a", "b"},
+			wantResults: []string{"a", "b"},
+		},
+		{
+			desc:        "one unknown",
+			client:      &mockClient{queries: map[string]*fleet.HostRemoteQuery{"a": pending("SELECT 1")}},
+			execIDs:     []string{"b", "a"},
+			errContains: "no such remote query: b",
+			wantResults: []string{"a"},
+		},
+		{
+			desc: "already completed",
+			client: &mockClient{queries: map[string]*fleet.HostRemoteQuery{
+				"a": {Status: fleet.HostRemoteQueryStatusCompleted, Query: "SELECT 1"},
+			}},
+			execIDs: []string{"a"},
+		},
+		{
+			desc:   "query fails",
+			client: &mockClient{queries: map[string]*fleet.HostRemoteQuery{"a": pending("SELECT * FROM nope")}},
+			queryFn: func(ctx context.Context, socket, sql string) ([]map[string]string, error) {
+				return nil, errors.New("no such table: nope")
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "run query: no such table: nope"},
+		},
+		{
+			desc:   "too many rows",
+			client: &mockClient{queries: map[string]*fleet.HostRemoteQuery{"a": pending("SELECT * FROM file")}},
+			queryFn: func(ctx context.Context, socket, sql string) ([]map[string]string, error) {
+				return make([]map[string]string, fleet.MaxHostRemoteQueryRows+1), nil
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "too many rows"},
+		},
+		{
+			desc:   "results too large",
+			client: &mockClient{queries: map[string]*fleet.HostRemoteQuery{"a": pending("SELECT * FROM file_lines")}},
+			queryFn: func(ctx context.Context, socket, sql string) ([]map[string]string, error) {
+				return []map[string]string{{"line": strings.Repeat("a", fleet.MaxHostRemoteQueryResultSize)}}, nil
+			},
+			execIDs:         []string{"a"},
+			wantResults:     []string{"a"},
+			wantResultError: map[string]string{"a": "results are too large"},
+		},
+		{
+			desc:    "save fails",
+			client:  &mockClient{queries: map[string]*fleet.HostRemoteQuery{"a": pending("SELECT 1")}, saveErr: io.ErrUnexpectedEOF},
+			execIDs: []string{"a"},
+
+			errContains: "save remote query result: unexpected EOF",
+			wantResults: []string{"a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			queryFn := c.queryFn
+			if queryFn == nil {
+				queryFn = func(ctx context.Context, socket, sql string) ([]map[string]string, error) {
+					require.Equal(t, "/tmp/osquery.em", socket)
+					return []map[string]string{{"sql": sql}}, nil
+				}
+			}
+			for id, rq := range c.client.queries {
+				rq.ExecutionID = id
+			}
+
+			runner := &Runner{Client: c.client, ExtensionSocket: "/tmp/osquery.em", queryFn: queryFn}
+			err := runner.Run(c.execIDs)
+			if c.errContains != "" {
+				require.ErrorContains(t, err, c.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, c.client.results, len(c.wantResults))
+			for _, id := range c.wantResults {
+				res := c.client.results[id]
+				require.NotNil(t, res, id)
+				if msg := c.wantResultError[id]; msg != "" {
+					require.Contains(t, res.Error, msg)
+					require.Empty(t, res.Rows)
+					continue
+				}
+				require.Empty(t, res.Error)
+				require.Equal(t, []map[string]string{{"sql": c.client.queries[id].Query}}, res.Rows)
+			}
+		})
+	}
+}
+
+type mockClient struct {
+	queries map[string]*fleet.HostRemoteQuery
+	results map[string]*fleet.HostRemoteQueryResultPayload
+	saveErr error
+}
+
+func (m *mockClient) GetHostRemoteQuery(execID string) (*fleet.HostRemoteQuery, error) {
+	rq := m.queries[execID]
+	if rq == nil {
+		return nil, fmt.Errorf("no such remote query: %s", execID)
+	}
+	return rq, nil
+}
+
+func (m *mockClient) SaveHostRemoteQueryResult(result *fleet.HostRemoteQueryResultPayload) error {
+	if m.results == nil {
+		m.results = make(map[string]*fleet.HostRemoteQueryResultPayload)
+	}
+	m.results[result.ExecutionID] = result
+	return m.saveErr
+}
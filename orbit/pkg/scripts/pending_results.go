@@ -0,0 +1,176 @@
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+const (
+	// pendingResultExt is the extension of the files that store a script
+	// result that has not been saved by the Fleet server yet.
+	pendingResultExt = ".result.json"
+	// runningMarkerExt is the extension of the files that mark a script as
+	// being executed.
+	runningMarkerExt = ".running"
+
+	// interruptedOutput is the output reported for a script whose execution
+	// was interrupted by orbit stopping, e.g. because the host shut down.
+	interruptedOutput = "Script execution was interrupted by orbit stopping (e.g. host shutdown or restart)."
+)
+
+// pendingResultsMu serializes the accesses to the pending results directory,
+// which may be flushed (e.g. by a power-event hook) while a script runs.
+var pendingResultsMu sync.Mutex
+
+// markRunning records that the script identified by execID is being
+// executed, so that its result can be reported if orbit stops before it
+// completes. It is a no-op if ResultsDir is not set.
+func (r *Runner) markRunning(execID string) error {
+	if r.ResultsDir == "" {
+		return nil
+	}
+
+	pendingResultsMu.Lock()
+	defer pendingResultsMu.Unlock()
+
+	if err := os.MkdirAll(r.ResultsDir, constant.DefaultDirMode); err != nil {
+		return fmt.Errorf("create pending results directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.ResultsDir, execID+runningMarkerExt), nil, constant.DefaultFileMode); err != nil {
+		return fmt.Errorf("write running marker: %w", err)
+	}
+	return nil
+}
+
+// saveResult persists the result on disk (if ResultsDir is set) before
+// sending it to the Fleet server, and removes it once it has been saved by
+// the server.
+func (r *Runner) saveResult(result *fleet.HostScriptResultPayload) error {
+	if r.ResultsDir == "" {
+		return r.Client.SaveHostScriptResult(result)
+	}
+
+	pendingResultsMu.Lock()
+	defer pendingResultsMu.Unlock()
+
+	if err := r.persistResultLocked(result); err != nil {
+		return err
+	}
+	return r.sendResultLocked(result)
+}
+
+func (r *Runner) persistResultLocked(result *fleet.HostScriptResultPayload) error {
+	if err := os.MkdirAll(r.ResultsDir, constant.DefaultDirMode); err != nil {
+		return fmt.Errorf("create pending results directory: %w", err)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal pending result: %w", err)
+	}
+	resultFile := filepath.Join(r.ResultsDir, result.ExecutionID+pendingResultExt)
+	if err := os.WriteFile(resultFile, b, constant.DefaultFileMode); err != nil {
+		return fmt.Errorf("write pending result: %w", err)
+	}
+	// the result replaces the running marker, if any
+	if err := os.Remove(filepath.Join(r.ResultsDir, result.ExecutionID+runningMarkerExt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove running marker: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) sendResultLocked(result *fleet.HostScriptResultPayload) error {
+	if err := r.Client.SaveHostScriptResult(result); err != nil {
+		var nfe interface{ NotFound() bool }
+		if errors.As(err, &nfe) && nfe.NotFound() {
+			// the execution does not exist anymore on the server (e.g. the host
+			// was deleted), the result will never be saved so it is not kept.
+			_ = os.Remove(filepath.Join(r.ResultsDir, result.ExecutionID+pendingResultExt))
+		}
+		return err
+	}
+	if err := os.Remove(filepath.Join(r.ResultsDir, result.ExecutionID+pendingResultExt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove pending result: %w", err)
+	}
+	return nil
+}
+
+// recoverInterruptedRuns stores an "interrupted" result for the scripts that
+// were marked as running but have no result, which happens when orbit was
+// stopped during their execution. It must only be called when no script is
+// running.
+func (r *Runner) recoverInterruptedRuns() error {
+	if r.ResultsDir == "" {
+		return nil
+	}
+
+	pendingResultsMu.Lock()
+	defer pendingResultsMu.Unlock()
+
+	markers, err := filepath.Glob(filepath.Join(r.ResultsDir, "*"+runningMarkerExt))
+	if err != nil {
+		return fmt.Errorf("list running markers: %w", err)
+	}
+	for _, marker := range markers {
+		execID := strings.TrimSuffix(filepath.Base(marker), runningMarkerExt)
+		if err := r.persistResultLocked(&fleet.HostScriptResultPayload{
+			ExecutionID: execID,
+			Output:      interruptedOutput,
+			ExitCode:    -1,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlushPendingResults sends the results persisted on disk that have not been
+// saved by the Fleet server yet, e.g. because the host went to sleep or
+// orbit stopped before they were sent. It stops early if ctx is done. It is
+// a no-op if ResultsDir is not set.
+func (r *Runner) FlushPendingResults(ctx context.Context) error {
+	if r.ResultsDir == "" {
+		return nil
+	}
+
+	pendingResultsMu.Lock()
+	defer pendingResultsMu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(r.ResultsDir, "*"+pendingResultExt))
+	if err != nil {
+		return fmt.Errorf("list pending results: %w", err)
+	}
+
+	var errs []error
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("read pending result: %w", err))
+			continue
+		}
+		var result fleet.HostScriptResultPayload
+		if err := json.Unmarshal(b, &result); err != nil {
+			// the file is corrupted, it will never be sent successfully
+			errs = append(errs, fmt.Errorf("unmarshal pending result %s: %w", file, err))
+			_ = os.Remove(file)
+			continue
+		}
+		if err := r.sendResultLocked(&result); err != nil {
+			errs = append(errs, fmt.Errorf("save pending script result: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
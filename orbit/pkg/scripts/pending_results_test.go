@@ -0,0 +1,103 @@
+package scripts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "not found" }
+func (notFoundError) NotFound() bool { return true }
+
+func TestRunnerPendingResults(t *testing.T) {
+	listDir := func(t *testing.T, dir string) []string {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	t.Run("result is removed once saved", func(t *testing.T) {
+		resultsDir := t.TempDir()
+		client := &mockClient{scripts: map[string]*fleet.HostScriptResult{"a": {ExecutionID: "a"}}}
+		execer := &mockExecCmd{output: []byte("output")}
+		runner := &Runner{
+			Client:                 client,
+			ScriptExecutionEnabled: true,
+			ResultsDir:             resultsDir,
+			tempDirFn:              t.TempDir,
+			execCmdFn:              execer.run,
+		}
+
+		require.NoError(t, runner.Run([]string{"a"}))
+		require.Equal(t, "output", client.results["a"].Output)
+		require.Empty(t, listDir(t, resultsDir))
+	})
+
+	t.Run("failed result is flushed later", func(t *testing.T) {
+		resultsDir := t.TempDir()
+		client := &mockClient{
+			saveErr: errFailOnce,
+			scripts: map[string]*fleet.HostScriptResult{"a": {ExecutionID: "a"}},
+		}
+		execer := &mockExecCmd{output: []byte("output")}
+		runner := &Runner{
+			Client:                 client,
+			ScriptExecutionEnabled: true,
+			ResultsDir:             resultsDir,
+			tempDirFn:              t.TempDir,
+			execCmdFn:              execer.run,
+		}
+
+		require.ErrorContains(t, runner.Run([]string{"a"}), "fail once")
+		require.Equal(t, []string{"a" + pendingResultExt}, listDir(t, resultsDir))
+
+		client.results = nil
+		require.NoError(t, runner.FlushPendingResults(context.Background()))
+		require.Equal(t, "output", client.results["a"].Output)
+		require.Empty(t, listDir(t, resultsDir))
+		require.Equal(t, 1, execer.count)
+	})
+
+	t.Run("interrupted script is reported", func(t *testing.T) {
+		resultsDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(resultsDir, "a"+runningMarkerExt), nil, 0o600))
+
+		client := &mockClient{}
+		runner := &Runner{Client: client, ScriptExecutionEnabled: true, ResultsDir: resultsDir}
+		require.NoError(t, runner.Run(nil))
+		require.Equal(t, interruptedOutput, client.results["a"].Output)
+		require.Equal(t, -1, client.results["a"].ExitCode)
+		require.Empty(t, listDir(t, resultsDir))
+	})
+
+	t.Run("not found result is dropped", func(t *testing.T) {
+		resultsDir := t.TempDir()
+		client := &mockClient{saveErr: notFoundError{}}
+		runner := &Runner{Client: client, ScriptExecutionEnabled: false, ResultsDir: resultsDir}
+
+		require.ErrorContains(t, runner.Run([]string{"a"}), "not found")
+		require.Empty(t, listDir(t, resultsDir))
+	})
+
+	t.Run("flush stops when context is done", func(t *testing.T) {
+		resultsDir := t.TempDir()
+		client := &mockClient{saveErr: errFailOnce}
+		runner := &Runner{Client: client, ScriptExecutionEnabled: false, ResultsDir: resultsDir}
+		require.Error(t, runner.Run([]string{"a"}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, runner.FlushPendingResults(ctx), context.Canceled)
+		require.Equal(t, []string{"a" + pendingResultExt}, listDir(t, resultsDir))
+	})
+}
@@ -31,6 +31,12 @@ type Runner struct {
 	Client                 Client
 	ScriptExecutionEnabled bool
 
+	// ResultsDir is the directory where the results are persisted until they
+	// are saved by the Fleet server, so that they are not lost if the host
+	// goes to sleep or orbit stops before they are sent. If empty, results
+	// are only kept in memory.
+	ResultsDir string
+
 	// tempDirFn is the function to call to get the temporary directory to use,
 	// inside of which the script-specific subdirectories will be created. If nil,
 	// the user's temp dir will be used (can be set to t.TempDir in tests).
@@ -50,6 +56,15 @@ type Runner struct {
 func (r *Runner) Run(execIDs []string) error {
 	var errs []error
 
+	// report the results that could not be sent previously before processing
+	// new scripts, so they are sent in the order of execution.
+	if err := r.recoverInterruptedRuns(); err != nil {
+		errs = append(errs, fmt.Errorf("recover interrupted scripts: %w", err))
+	}
+	if err := r.FlushPendingResults(context.Background()); err != nil {
+		errs = append(errs, err)
+	}
+
 	for _, execID := range execIDs {
 		if !r.ScriptExecutionEnabled {
 			if err := r.runOneDisabled(execID); err != nil {
@@ -85,6 +100,10 @@ func (r *Runner) runOne(script *fleet.HostScriptResult) (finalErr error) {
 		return nil
 	}
 
+	if err := r.markRunning(script.ExecutionID); err != nil {
+		return err
+	}
+
 	runDir, err := r.createRunDir(script.ExecutionID)
 	if err != nil {
 		return fmt.Errorf("create run directory: %w", err)
@@ -135,7 +154,7 @@ func (r *Runner) runOne(script *fleet.HostScriptResult) (finalErr error) {
 		output = output[len(output)-(utf8.UTFMax*maxOutputRuneLen):]
 	}
 
-	err = r.Client.SaveHostScriptResult(&fleet.HostScriptResultPayload{
+	err = r.saveResult(&fleet.HostScriptResultPayload{
 		ExecutionID: script.ExecutionID,
 		Output:      string(output),
 		Runtime:     int(duration.Seconds()),
@@ -158,7 +177,7 @@ func (r *Runner) createRunDir(execID string) (string, error) {
 }
 
 func (r *Runner) runOneDisabled(execID string) error {
-	err := r.Client.SaveHostScriptResult(&fleet.HostScriptResultPayload{
+	err := r.saveResult(&fleet.HostScriptResultPayload{
 		ExecutionID: execID,
 		Output:      "Scripts are disabled",
 		ExitCode:    -2, // fleetctl knows that -2 means script was disabled on host
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package servicerecovery
+
+import "github.com/fleetdm/fleet/v4/server/fleet"
+
+// applyRecoveryActions is a no-op, the recovery actions are only supported
+// by the Windows service control manager.
+func applyRecoveryActions(cfg *fleet.OrbitWindowsServiceConfig) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build windows
+// +build windows
+
+package servicerecovery
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func applyRecoveryActions(cfg *fleet.OrbitWindowsServiceConfig) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(constant.SystemServiceName)
+	if err != nil {
+		return fmt.Errorf("open service: %w", err)
+	}
+	defer s.Close()
+
+	if len(cfg.RecoveryActions) == 0 {
+		if err := s.ResetRecoveryActions(); err != nil {
+			return fmt.Errorf("reset recovery actions: %w", err)
+		}
+		return nil
+	}
+
+	actions := make([]mgr.RecoveryAction, 0, len(cfg.RecoveryActions))
+	for _, a := range cfg.RecoveryActions {
+		typ := mgr.NoAction
+		if a.Type == fleet.OrbitServiceRecoveryActionRestart {
+			typ = mgr.ServiceRestart
+		}
+		actions = append(actions, mgr.RecoveryAction{Type: typ, Delay: time.Duration(a.DelaySeconds) * time.Second})
+	}
+	if err := s.SetRecoveryActions(actions, uint32(cfg.ResetPeriodSeconds)); err != nil {
+		return fmt.Errorf("set recovery actions: %w", err)
+	}
+	// the watchdog exits with a non-zero exit code, make sure that it also
+	// triggers the recovery actions.
+	if err := s.SetRecoveryActionsOnNonCrashFailures(true); err != nil {
+		return fmt.Errorf("set recovery actions on non-crash failures: %w", err)
+	}
+	return nil
+}
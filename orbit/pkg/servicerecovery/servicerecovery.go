@@ -0,0 +1,259 @@
+// Package servicerecovery applies the recovery policies of the fleetd service
+// configured in Fleet, runs a watchdog that stops fleetd when its main loop
+// stops sending heartbeats (so that the service is restarted by the service
+// control manager), and reports to the Fleet server when fleetd was recovered
+// after a failure.
+package servicerecovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// stateFileName is the name of the file, in orbit's root directory, where
+	// the state of the manager is persisted across orbit restarts.
+	stateFileName = "service_recovery_state.json"
+
+	defaultCheckInterval = 15 * time.Second
+)
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	ReportServiceRecovery(recovery *fleet.HostServiceRecoveryPayload) error
+}
+
+// state is the state of the manager persisted on disk.
+type state struct {
+	// Running is true while fleetd runs. If it is still true when fleetd
+	// starts, fleetd did not shut down cleanly.
+	Running bool `json:"running"`
+	// WatchdogFiredAt is the time at which the watchdog stopped fleetd, if it
+	// did.
+	WatchdogFiredAt *time.Time `json:"watchdog_fired_at,omitempty"`
+	// LastHeartbeat is the time of the last heartbeat before the watchdog
+	// stopped fleetd.
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+	// PendingRecoveries are the recoveries that have not been reported yet.
+	PendingRecoveries []fleet.HostServiceRecoveryPayload `json:"pending_recoveries"`
+}
+
+func readState(path string) (*state, error) {
+	var st state
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// no state yet
+	case err != nil:
+		return nil, fmt.Errorf("read service recovery state: %w", err)
+	default:
+		if err := json.Unmarshal(b, &st); err != nil {
+			log.Info().Err(err).Msg("discarding invalid service recovery state")
+			st = state{}
+		}
+	}
+	return &st, nil
+}
+
+func writeState(path string, st *state) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("marshal service recovery state: %w", err)
+	}
+	if err := os.WriteFile(path, b, constant.DefaultFileMode); err != nil {
+		return fmt.Errorf("write service recovery state: %w", err)
+	}
+	return nil
+}
+
+// Manager applies the service settings received from the Fleet server, runs
+// the watchdog and reports the recoveries. It is designed with Execute and
+// Interrupt functions to be compatible with oklog/run.
+type Manager struct {
+	client        Client
+	statePath     string
+	checkInterval time.Duration
+
+	// applyFn applies the recovery actions to the fleetd service.
+	applyFn func(cfg *fleet.OrbitWindowsServiceConfig) error
+	// exitFn is called when the watchdog fires, it must stop fleetd without
+	// a clean shutdown so that the recovery actions are triggered.
+	exitFn func()
+	nowFn  func() time.Time
+
+	mu               sync.Mutex
+	applied          *fleet.OrbitWindowsServiceConfig
+	heartbeatTimeout time.Duration
+	lastHeartbeat    time.Time
+
+	interruptCh chan struct{}
+	interruptMu sync.Once
+}
+
+// NewManager returns a Manager that persists its state in rootDir and reports
+// the recoveries with client.
+func NewManager(rootDir string, client Client) *Manager {
+	return &Manager{
+		client:        client,
+		statePath:     filepath.Join(rootDir, stateFileName),
+		checkInterval: defaultCheckInterval,
+		applyFn:       applyRecoveryActions,
+		exitFn:        func() { os.Exit(1) },
+		nowFn:         time.Now,
+		lastHeartbeat: time.Now(),
+		interruptCh:   make(chan struct{}),
+	}
+}
+
+// Heartbeat records that fleetd's main loop is alive.
+func (m *Manager) Heartbeat() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastHeartbeat = m.nowFn()
+}
+
+// ApplyConfig applies the recovery actions of cfg to the fleetd service if
+// they changed since the last call, and updates the watchdog heartbeat. A nil
+// cfg disables the watchdog and leaves the recovery actions unchanged.
+func (m *Manager) ApplyConfig(cfg *fleet.OrbitWindowsServiceConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cfg == nil {
+		m.heartbeatTimeout = 0
+		return nil
+	}
+	m.heartbeatTimeout = time.Duration(cfg.WatchdogHeartbeatSeconds) * time.Second
+
+	if m.applied != nil && reflect.DeepEqual(m.applied.RecoveryActions, cfg.RecoveryActions) &&
+		m.applied.ResetPeriodSeconds == cfg.ResetPeriodSeconds {
+		return nil
+	}
+	if err := m.applyFn(cfg); err != nil {
+		return fmt.Errorf("apply service recovery actions: %w", err)
+	}
+	applied := *cfg
+	m.applied = &applied
+	log.Info().Msgf("applied %d service recovery actions", len(cfg.RecoveryActions))
+	return nil
+}
+
+// Execute records that fleetd is running, reports the recovery if fleetd did
+// not shut down cleanly, and then checks the watchdog every check interval
+// until Interrupt is called.
+func (m *Manager) Execute() error {
+	st, err := m.start()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(m.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if len(st.PendingRecoveries) > 0 {
+			if err := m.report(st); err != nil {
+				log.Info().Err(err).Msg("report service recovery")
+			}
+		}
+		if m.watchdogExpired(st) {
+			m.exitFn()
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.interruptCh:
+			st.Running = false
+			return writeState(m.statePath, st)
+		}
+	}
+}
+
+// Interrupt stops the manager.
+func (m *Manager) Interrupt(err error) {
+	log.Debug().Err(err).Msg("interrupt service recovery manager")
+	m.interruptMu.Do(func() { close(m.interruptCh) })
+}
+
+// start reads the persisted state, records a pending recovery if fleetd did
+// not shut down cleanly and marks fleetd as running.
+func (m *Manager) start() (*state, error) {
+	st, err := readState(m.statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Running {
+		recovery := fleet.HostServiceRecoveryPayload{
+			Reason:      fleet.HostServiceRecoveryCrash,
+			Details:     "fleetd stopped unexpectedly",
+			RecoveredAt: m.nowFn().UTC(),
+		}
+		if st.WatchdogFiredAt != nil {
+			recovery.Reason = fleet.HostServiceRecoveryWatchdog
+			recovery.Details = fmt.Sprintf("fleetd stopped by the watchdog at %s", st.WatchdogFiredAt.UTC().Format(time.RFC3339))
+			if st.LastHeartbeat != nil {
+				recovery.Details += fmt.Sprintf(", no heartbeat since %s", st.LastHeartbeat.UTC().Format(time.RFC3339))
+			}
+		}
+		log.Info().Msgf("fleetd recovered: %s", recovery.Details)
+		st.PendingRecoveries = append(st.PendingRecoveries, recovery)
+	}
+
+	st.Running = true
+	st.WatchdogFiredAt = nil
+	st.LastHeartbeat = nil
+	if err := writeState(m.statePath, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// report reports the pending recoveries, the ones that were reported are
+// removed from the state.
+func (m *Manager) report(st *state) error {
+	var reportErr error
+	for len(st.PendingRecoveries) > 0 {
+		if err := m.client.ReportServiceRecovery(&st.PendingRecoveries[0]); err != nil {
+			reportErr = err
+			break
+		}
+		st.PendingRecoveries = st.PendingRecoveries[1:]
+	}
+	return errors.Join(reportErr, writeState(m.statePath, st))
+}
+
+// watchdogExpired returns true if the watchdog is enabled and no heartbeat
+// was received within the heartbeat timeout. The time at which the watchdog
+// fired is persisted so that the recovery is reported as such on the next
+// start.
+func (m *Manager) watchdogExpired(st *state) bool {
+	m.mu.Lock()
+	timeout, lastHeartbeat := m.heartbeatTimeout, m.lastHeartbeat
+	m.mu.Unlock()
+
+	now := m.nowFn()
+	if timeout <= 0 || now.Sub(lastHeartbeat) <= timeout {
+		return false
+	}
+
+	log.Error().Msgf("watchdog: no heartbeat since %s, stopping fleetd", lastHeartbeat.UTC().Format(time.RFC3339))
+	st.WatchdogFiredAt = &now
+	st.LastHeartbeat = &lastHeartbeat
+	if err := writeState(m.statePath, st); err != nil {
+		log.Error().Err(err).Msg("watchdog")
+	}
+	return true
+}
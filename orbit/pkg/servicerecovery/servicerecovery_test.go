@@ -0,0 +1,184 @@
+package servicerecovery
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	mu         sync.Mutex
+	recoveries []*fleet.HostServiceRecoveryPayload
+	err        error
+}
+
+func (m *mockClient) ReportServiceRecovery(recovery *fleet.HostServiceRecoveryPayload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.recoveries = append(m.recoveries, recovery)
+	return nil
+}
+
+func newTestManager(t *testing.T, rootDir string, client Client) *Manager {
+	m := NewManager(rootDir, client)
+	m.applyFn = func(cfg *fleet.OrbitWindowsServiceConfig) error { return nil }
+	m.exitFn = func() {}
+	return m
+}
+
+func TestManagerApplyConfig(t *testing.T) {
+	m := newTestManager(t, t.TempDir(), &mockClient{})
+
+	var applied []*fleet.OrbitWindowsServiceConfig
+	var applyErr error
+	m.applyFn = func(cfg *fleet.OrbitWindowsServiceConfig) error {
+		if applyErr != nil {
+			return applyErr
+		}
+		applied = append(applied, cfg)
+		return nil
+	}
+
+	cfg := &fleet.OrbitWindowsServiceConfig{
+		RecoveryActions:          []fleet.OrbitServiceRecoveryAction{{Type: fleet.OrbitServiceRecoveryActionRestart, DelaySeconds: 60}},
+		ResetPeriodSeconds:       3600,
+		WatchdogHeartbeatSeconds: 300,
+	}
+	require.NoError(t, m.ApplyConfig(cfg))
+	require.Len(t, applied, 1)
+	require.Equal(t, 300*time.Second, m.heartbeatTimeout)
+
+	// unchanged recovery actions are not applied again
+	cfg2 := *cfg
+	cfg2.WatchdogHeartbeatSeconds = 600
+	require.NoError(t, m.ApplyConfig(&cfg2))
+	require.Len(t, applied, 1)
+	require.Equal(t, 600*time.Second, m.heartbeatTimeout)
+
+	// failures are retried on the next config
+	cfg2.ResetPeriodSeconds = 60
+	applyErr = errors.New("access denied")
+	require.ErrorContains(t, m.ApplyConfig(&cfg2), "access denied")
+	applyErr = nil
+	require.NoError(t, m.ApplyConfig(&cfg2))
+	require.Len(t, applied, 2)
+	require.Equal(t, 60, applied[1].ResetPeriodSeconds)
+
+	// no config disables the watchdog
+	require.NoError(t, m.ApplyConfig(nil))
+	require.Len(t, applied, 2)
+	require.Zero(t, m.heartbeatTimeout)
+}
+
+func TestManagerReportsCrash(t *testing.T) {
+	rootDir := t.TempDir()
+	client := &mockClient{}
+	now := time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC)
+
+	// first start, nothing to report
+	m := newTestManager(t, rootDir, client)
+	m.nowFn = func() time.Time { return now }
+	st, err := m.start()
+	require.NoError(t, err)
+	require.True(t, st.Running)
+	require.Empty(t, st.PendingRecoveries)
+
+	// fleetd crashed (no clean shutdown) and the server is not reachable
+	client.err = errors.New("network error")
+	m = newTestManager(t, rootDir, client)
+	m.nowFn = func() time.Time { return now }
+	st, err = m.start()
+	require.NoError(t, err)
+	require.Len(t, st.PendingRecoveries, 1)
+	require.ErrorContains(t, m.report(st), "network error")
+	require.Empty(t, client.recoveries)
+
+	// crashed again, both recoveries are reported once the server is
+	// reachable
+	client.err = nil
+	m = newTestManager(t, rootDir, client)
+	m.nowFn = func() time.Time { return now }
+	st, err = m.start()
+	require.NoError(t, err)
+	require.Len(t, st.PendingRecoveries, 2)
+	require.NoError(t, m.report(st))
+	require.Len(t, client.recoveries, 2)
+	for _, r := range client.recoveries {
+		require.Equal(t, fleet.HostServiceRecoveryCrash, r.Reason)
+		require.Equal(t, now, r.RecoveredAt)
+	}
+	st, err = readState(m.statePath)
+	require.NoError(t, err)
+	require.Empty(t, st.PendingRecoveries)
+}
+
+func TestManagerCleanShutdown(t *testing.T) {
+	rootDir := t.TempDir()
+	client := &mockClient{}
+
+	m := newTestManager(t, rootDir, client)
+	m.checkInterval = time.Millisecond
+	done := make(chan error)
+	go func() { done <- m.Execute() }()
+	time.Sleep(10 * time.Millisecond)
+	m.Interrupt(nil)
+	require.NoError(t, <-done)
+
+	st, err := readState(m.statePath)
+	require.NoError(t, err)
+	require.False(t, st.Running)
+
+	// nothing is reported after a clean shutdown
+	m = newTestManager(t, rootDir, client)
+	st, err = m.start()
+	require.NoError(t, err)
+	require.Empty(t, st.PendingRecoveries)
+}
+
+func TestManagerWatchdog(t *testing.T) {
+	rootDir := t.TempDir()
+	client := &mockClient{}
+	now := time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC)
+
+	m := newTestManager(t, rootDir, client)
+	m.nowFn = func() time.Time { return now }
+	m.Heartbeat()
+	st := &state{}
+
+	// watchdog disabled
+	now = now.Add(time.Hour)
+	require.False(t, m.watchdogExpired(st))
+
+	require.NoError(t, m.ApplyConfig(&fleet.OrbitWindowsServiceConfig{WatchdogHeartbeatSeconds: 300}))
+	m.Heartbeat()
+	now = now.Add(4 * time.Minute)
+	require.False(t, m.watchdogExpired(st))
+	lastHeartbeat := now
+	m.Heartbeat()
+
+	// no heartbeat within the timeout, the watchdog stops fleetd
+	now = now.Add(6 * time.Minute)
+	var exited bool
+	m.exitFn = func() { exited = true }
+	m.checkInterval = time.Hour
+	require.NoError(t, m.Execute())
+	require.True(t, exited)
+
+	// the recovery is reported as a watchdog recovery on the next start
+	m = newTestManager(t, rootDir, client)
+	m.nowFn = func() time.Time { return now.Add(time.Minute) }
+	st, err := m.start()
+	require.NoError(t, err)
+	require.NoError(t, m.report(st))
+	require.Len(t, client.recoveries, 1)
+	require.Equal(t, fleet.HostServiceRecoveryWatchdog, client.recoveries[0].Reason)
+	require.Contains(t, client.recoveries[0].Details, "no heartbeat since "+lastHeartbeat.Format(time.RFC3339))
+	require.Equal(t, now.Add(time.Minute), client.recoveries[0].RecoveredAt)
+}
@@ -0,0 +1,350 @@
+// Package cloud_instance_metadata implements the cloud_instance_metadata
+// table, that reports the identity of the cloud instance (AWS EC2, Google
+// Compute Engine or Azure virtual machine) the host runs on, as read from the
+// instance metadata service of the cloud provider.
+package cloud_instance_metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	providerAWS   = "aws"
+	providerGCP   = "gcp"
+	providerAzure = "azure"
+
+	// requestTimeout is the timeout of each request to the instance metadata
+	// services. They are link-local and answer within a few milliseconds, so
+	// a short timeout keeps the table fast on hosts that are not cloud
+	// instances.
+	requestTimeout = 1 * time.Second
+	// cacheTTL is the duration the metadata (or the absence of metadata) is
+	// cached for, as it does not change for the lifetime of an instance
+	// (except for the tags).
+	cacheTTL = 1 * time.Hour
+	// maxResponseSize is the maximum size of a metadata service response
+	// that is read.
+	maxResponseSize = 1 << 20
+)
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("provider"),
+		table.TextColumn("instance_id"),
+		table.TextColumn("instance_type"),
+		table.TextColumn("region"),
+		table.TextColumn("zone"),
+		table.TextColumn("account_id"),
+		table.TextColumn("tags"),
+	}
+}
+
+// GenerateFunc is called to return the results for the table at query time.
+// It returns a single row if the host is a cloud instance and no row
+// otherwise.
+func GenerateFunc(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	return defaultDetector.generate(ctx)
+}
+
+var defaultDetector = &detector{
+	client: &http.Client{
+		// the metadata services must never be reached through a proxy.
+		Transport: &http.Transport{Proxy: nil},
+		// the metadata services do not redirect, a redirect is not expected.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	},
+	awsURL:   "http://169.254.169.254",
+	gcpURL:   "http://metadata.google.internal",
+	azureURL: "http://169.254.169.254",
+}
+
+// instance is the metadata of a cloud instance.
+type instance struct {
+	provider     string
+	instanceID   string
+	instanceType string
+	region       string
+	zone         string
+	accountID    string
+	tags         map[string]string
+}
+
+func (i *instance) row() (map[string]string, error) {
+	tags := i.tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"provider":      i.provider,
+		"instance_id":   i.instanceID,
+		"instance_type": i.instanceType,
+		"region":        i.region,
+		"zone":          i.zone,
+		"account_id":    i.accountID,
+		"tags":          string(b),
+	}, nil
+}
+
+type detector struct {
+	client   *http.Client
+	awsURL   string
+	gcpURL   string
+	azureURL string
+
+	mu        sync.Mutex
+	cached    *instance
+	checkedAt time.Time
+}
+
+func (d *detector) generate(ctx context.Context) ([]map[string]string, error) {
+	inst := d.detect(ctx)
+	if inst == nil {
+		return nil, nil
+	}
+	row, err := inst.row()
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]string{row}, nil
+}
+
+// detect returns the metadata of the cloud instance, or nil if the host is
+// not a cloud instance of a supported provider.
+func (d *detector) detect(ctx context.Context) *instance {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.checkedAt.IsZero() && time.Since(d.checkedAt) < cacheTTL {
+		return d.cached
+	}
+
+	detectors := []struct {
+		provider string
+		fn       func(context.Context) (*instance, error)
+	}{
+		{providerAWS, d.detectAWS},
+		{providerGCP, d.detectGCP},
+		{providerAzure, d.detectAzure},
+	}
+	var inst *instance
+	for _, det := range detectors {
+		var err error
+		inst, err = det.fn(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				// do not cache the result if the query was canceled.
+				return nil
+			}
+			log.Debug().Err(err).Str("provider", det.provider).Msg("cloud_instance_metadata: detect instance")
+			continue
+		}
+		break
+	}
+	d.cached = inst
+	d.checkedAt = time.Now()
+	return inst
+}
+
+// detectAWS reads the metadata of an EC2 instance, using the session tokens
+// of IMDSv2. The tags are only available if access to the tags is allowed in
+// the instance metadata options.
+func (d *detector) detectAWS(ctx context.Context) (*instance, error) {
+	token, err := d.get(ctx, http.MethodPut, d.awsURL+"/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": "60",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get IMDSv2 token: %w", err)
+	}
+	hdrs := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+
+	b, err := d.get(ctx, http.MethodGet, d.awsURL+"/latest/dynamic/instance-identity/document", hdrs)
+	if err != nil {
+		return nil, fmt.Errorf("get instance identity document: %w", err)
+	}
+	var doc struct {
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		AccountID        string `json:"accountId"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal instance identity document: %w", err)
+	}
+	if doc.InstanceID == "" {
+		return nil, errors.New("missing instance ID in instance identity document")
+	}
+
+	tags := make(map[string]string)
+	b, err = d.get(ctx, http.MethodGet, d.awsURL+"/latest/meta-data/tags/instance", hdrs)
+	if err != nil {
+		log.Debug().Err(err).Msg("cloud_instance_metadata: get EC2 instance tags")
+	} else {
+		for _, key := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			if key == "" {
+				continue
+			}
+			v, err := d.get(ctx, http.MethodGet, d.awsURL+"/latest/meta-data/tags/instance/"+url.PathEscape(key), hdrs)
+			if err != nil {
+				log.Debug().Err(err).Str("key", key).Msg("cloud_instance_metadata: get EC2 instance tag")
+				continue
+			}
+			tags[key] = string(v)
+		}
+	}
+
+	return &instance{
+		provider:     providerAWS,
+		instanceID:   doc.InstanceID,
+		instanceType: doc.InstanceType,
+		region:       doc.Region,
+		zone:         doc.AvailabilityZone,
+		accountID:    doc.AccountID,
+		tags:         tags,
+	}, nil
+}
+
+// detectGCP reads the metadata of a Compute Engine instance. The labels of
+// the instance are not available from the metadata server, so the network
+// tags of the instance are reported as tags, with an empty value.
+func (d *detector) detectGCP(ctx context.Context) (*instance, error) {
+	hdrs := map[string]string{"Metadata-Flavor": "Google"}
+
+	b, err := d.get(ctx, http.MethodGet, d.gcpURL+"/computeMetadata/v1/instance/?recursive=true", hdrs)
+	if err != nil {
+		return nil, fmt.Errorf("get instance metadata: %w", err)
+	}
+	var md struct {
+		// the ID is a 64-bit unsigned integer, it is decoded as a json.Number
+		// so that it does not lose precision.
+		ID          json.Number `json:"id"`
+		MachineType string      `json:"machineType"`
+		Zone        string      `json:"zone"`
+		Tags        []string    `json:"tags"`
+	}
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, fmt.Errorf("unmarshal instance metadata: %w", err)
+	}
+	if md.ID == "" {
+		return nil, errors.New("missing instance ID in instance metadata")
+	}
+
+	projectID, err := d.get(ctx, http.MethodGet, d.gcpURL+"/computeMetadata/v1/project/project-id", hdrs)
+	if err != nil {
+		return nil, fmt.Errorf("get project ID: %w", err)
+	}
+
+	// the zone and machine type are reported as
+	// "projects/<project number>/zones/<zone>" and
+	// "projects/<project number>/machineTypes/<machine type>".
+	zone := lastPathSegment(md.Zone)
+	tags := make(map[string]string, len(md.Tags))
+	for _, tag := range md.Tags {
+		tags[tag] = ""
+	}
+	return &instance{
+		provider:     providerGCP,
+		instanceID:   md.ID.String(),
+		instanceType: lastPathSegment(md.MachineType),
+		region:       gcpRegion(zone),
+		zone:         zone,
+		accountID:    string(projectID),
+		tags:         tags,
+	}, nil
+}
+
+// detectAzure reads the metadata of an Azure virtual machine.
+func (d *detector) detectAzure(ctx context.Context) (*instance, error) {
+	b, err := d.get(ctx, http.MethodGet, d.azureURL+"/metadata/instance/compute?api-version=2021-02-01", map[string]string{
+		"Metadata": "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get instance metadata: %w", err)
+	}
+	var md struct {
+		VMID           string `json:"vmId"`
+		VMSize         string `json:"vmSize"`
+		Location       string `json:"location"`
+		Zone           string `json:"zone"`
+		SubscriptionID string `json:"subscriptionId"`
+		TagsList       []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"tagsList"`
+	}
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, fmt.Errorf("unmarshal instance metadata: %w", err)
+	}
+	if md.VMID == "" {
+		return nil, errors.New("missing VM ID in instance metadata")
+	}
+
+	tags := make(map[string]string, len(md.TagsList))
+	for _, tag := range md.TagsList {
+		tags[tag.Name] = tag.Value
+	}
+	return &instance{
+		provider:     providerAzure,
+		instanceID:   md.VMID,
+		instanceType: md.VMSize,
+		region:       md.Location,
+		zone:         md.Zone,
+		accountID:    md.SubscriptionID,
+		tags:         tags,
+	}, nil
+}
+
+func (d *detector) get(ctx context.Context, method, u string, hdrs map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hdrs {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+}
+
+func lastPathSegment(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
+
+// gcpRegion returns the region of a Compute Engine zone, e.g. "us-central1"
+// for "us-central1-a".
+func gcpRegion(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
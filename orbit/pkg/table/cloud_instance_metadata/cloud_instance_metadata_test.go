@@ -0,0 +1,163 @@
+package cloud_instance_metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDetector(aws, gcp, azure *httptest.Server) *detector {
+	d := &detector{client: http.DefaultClient}
+	for _, s := range []struct {
+		srv *httptest.Server
+		url *string
+	}{{aws, &d.awsURL}, {gcp, &d.gcpURL}, {azure, &d.azureURL}} {
+		if s.srv != nil {
+			*s.url = s.srv.URL
+		} else {
+			// a URL that fails immediately
+			*s.url = "http://127.0.0.1:0"
+		}
+	}
+	return d
+}
+
+func TestGenerateAWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			require.Equal(t, http.MethodPut, r.Method)
+			require.Equal(t, "60", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			_, _ = w.Write([]byte("token"))
+			return
+		}
+		require.Equal(t, "token", r.Header.Get("X-aws-ec2-metadata-token"))
+		switch r.URL.Path {
+		case "/latest/dynamic/instance-identity/document":
+			_, _ = w.Write([]byte(`{"accountId": "123456789012", "availabilityZone": "us-east-1a", "instanceId": "i-0abc", "instanceType": "t3.micro", "region": "us-east-1"}`))
+		case "/latest/meta-data/tags/instance":
+			_, _ = w.Write([]byte("Name\nenv"))
+		case "/latest/meta-data/tags/instance/Name":
+			_, _ = w.Write([]byte("web-1"))
+		case "/latest/meta-data/tags/instance/env":
+			_, _ = w.Write([]byte("prod"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rows, err := newTestDetector(srv, nil, nil).generate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{{
+		"provider":      "aws",
+		"instance_id":   "i-0abc",
+		"instance_type": "t3.micro",
+		"region":        "us-east-1",
+		"zone":          "us-east-1a",
+		"account_id":    "123456789012",
+		"tags":          `{"Name":"web-1","env":"prod"}`,
+	}}, rows)
+}
+
+func TestGenerateAWSTagsDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			_, _ = w.Write([]byte("token"))
+		case "/latest/dynamic/instance-identity/document":
+			_, _ = w.Write([]byte(`{"accountId": "123456789012", "instanceId": "i-0abc", "region": "us-east-1"}`))
+		default:
+			// access to the tags is not allowed by the metadata options
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rows, err := newTestDetector(srv, nil, nil).generate(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "i-0abc", rows[0]["instance_id"])
+	require.Equal(t, "{}", rows[0]["tags"])
+}
+
+func TestGenerateGCP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/":
+			require.Equal(t, "true", r.URL.Query().Get("recursive"))
+			_, _ = w.Write([]byte(`{"id": 8862617829912345678, "machineType": "projects/1234/machineTypes/e2-medium", "zone": "projects/1234/zones/us-central1-a", "tags": ["http-server"]}`))
+		case "/computeMetadata/v1/project/project-id":
+			_, _ = w.Write([]byte("my-project"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rows, err := newTestDetector(nil, srv, nil).generate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{{
+		"provider":      "gcp",
+		"instance_id":   "8862617829912345678",
+		"instance_type": "e2-medium",
+		"region":        "us-central1",
+		"zone":          "us-central1-a",
+		"account_id":    "my-project",
+		"tags":          `{"http-server":""}`,
+	}}, rows)
+}
+
+func TestGenerateAzure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/instance/compute" {
+			// the AWS token request is made to the same address
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.Equal(t, "true", r.Header.Get("Metadata"))
+		_, _ = w.Write([]byte(`{"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "vmSize": "Standard_B2s", "location": "westeurope", "zone": "1", "subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d", "tagsList": [{"name": "env", "value": "prod"}]}`))
+	}))
+	defer srv.Close()
+
+	rows, err := newTestDetector(srv, nil, srv).generate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{{
+		"provider":      "azure",
+		"instance_id":   "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		"instance_type": "Standard_B2s",
+		"region":        "westeurope",
+		"zone":          "1",
+		"account_id":    "8d10da13-8125-4ba9-a717-bf7490507b3d",
+		"tags":          `{"env":"prod"}`,
+	}}, rows)
+}
+
+func TestGenerateNotCloud(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	d := newTestDetector(srv, srv, srv)
+	rows, err := d.generate(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, rows)
+	require.Equal(t, 3, calls)
+
+	// the absence of metadata is cached
+	rows, err = d.generate(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, rows)
+	require.Equal(t, 3, calls)
+}
+
+func TestGcpRegion(t *testing.T) {
+	require.Equal(t, "europe-west1", gcpRegion("europe-west1-b"))
+	require.Equal(t, "", gcpRegion(""))
+}
@@ -8,9 +8,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/cloud_instance_metadata"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/cryptoinfotable"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/dataflattentable"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/firefox_preferences"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/fleetd_performance"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/smart_disk_health"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/sntp_request"
 	"github.com/macadmins/osquery-extension/tables/chromeuserprofiles"
 	"github.com/macadmins/osquery-extension/tables/fileline"
@@ -24,6 +27,8 @@ import (
 type Runner struct {
 	socket          string
 	tableExtensions []Extension
+	// tableOverrides enables or disables tables regardless of their default.
+	tableOverrides map[string]bool
 
 	// mu protects access to srv and cancel in Execute and Interrupt.
 	mu     sync.Mutex
@@ -54,6 +59,30 @@ func WithExtension(t Extension) Opt {
 	}
 }
 
+// WithEnabledTables enables the given tables, including those that are
+// disabled by default.
+func WithEnabledTables(names ...string) Opt {
+	return func(r *Runner) {
+		r.setTableOverrides(names, true)
+	}
+}
+
+// WithDisabledTables disables the given tables.
+func WithDisabledTables(names ...string) Opt {
+	return func(r *Runner) {
+		r.setTableOverrides(names, false)
+	}
+}
+
+func (r *Runner) setTableOverrides(names []string, enabled bool) {
+	if r.tableOverrides == nil {
+		r.tableOverrides = make(map[string]bool)
+	}
+	for _, name := range names {
+		r.tableOverrides[name] = enabled
+	}
+}
+
 // NewRunner creates an extension runner.
 func NewRunner(socket string, opts ...Opt) *Runner {
 	r := &Runner{socket: socket}
@@ -102,17 +131,16 @@ func (r *Runner) Execute() error {
 		}
 	}
 
-	plugins := OrbitDefaultTables()
-
-	plugins = append(plugins, PlatformTables()...)
+	registry := NewRegistry()
+	registry.Register(OrbitDefaultTables()...)
+	registry.Register(PlatformTables()...)
 	for _, t := range r.tableExtensions {
-		plugins = append(plugins, table.NewPlugin(
-			t.Name(),
-			t.Columns(),
-			t.GenerateFunc,
-		))
+		registry.Register(Table(t.Name(), t.Columns(), t.GenerateFunc))
+	}
+	for name, enabled := range r.tableOverrides {
+		registry.SetEnabled(name, enabled)
 	}
-	r.srv.RegisterPlugin(plugins...)
+	r.srv.RegisterPlugin(registry.Plugins()...)
 
 	if err := r.srv.Run(); err != nil {
 		return err
@@ -121,29 +149,33 @@ func (r *Runner) Execute() error {
 	return nil
 }
 
-func OrbitDefaultTables() []osquery.OsqueryPlugin {
-	plugins := []osquery.OsqueryPlugin{
+// OrbitDefaultTables returns the extension tables of fleetd that are
+// available on all platforms.
+func OrbitDefaultTables() []TableSpec {
+	return []TableSpec{
 		// MacAdmins extensions.
-		table.NewPlugin("puppet_info", puppet.PuppetInfoColumns(), puppet.PuppetInfoGenerate),
-		table.NewPlugin("puppet_logs", puppet.PuppetLogsColumns(), puppet.PuppetLogsGenerate),
-		table.NewPlugin("puppet_state", puppet.PuppetStateColumns(), puppet.PuppetStateGenerate),
-		table.NewPlugin("google_chrome_profiles", chromeuserprofiles.GoogleChromeProfilesColumns(), chromeuserprofiles.GoogleChromeProfilesGenerate),
-		table.NewPlugin("file_lines", fileline.FileLineColumns(), fileline.FileLineGenerate),
+		Table("puppet_info", puppet.PuppetInfoColumns(), puppet.PuppetInfoGenerate),
+		Table("puppet_logs", puppet.PuppetLogsColumns(), puppet.PuppetLogsGenerate),
+		Table("puppet_state", puppet.PuppetStateColumns(), puppet.PuppetStateGenerate),
+		Table("google_chrome_profiles", chromeuserprofiles.GoogleChromeProfilesColumns(), chromeuserprofiles.GoogleChromeProfilesGenerate),
+		Table("file_lines", fileline.FileLineColumns(), fileline.FileLineGenerate),
 
 		// Orbit extensions.
-		table.NewPlugin("sntp_request", sntp_request.Columns(), sntp_request.GenerateFunc),
+		Table("sntp_request", sntp_request.Columns(), sntp_request.GenerateFunc),
+		Table("fleetd_performance", fleetd_performance.Columns(), fleetd_performance.GenerateFunc),
+		Table("cloud_instance_metadata", cloud_instance_metadata.Columns(), cloud_instance_metadata.GenerateFunc),
+		Table("smart_disk_health", smart_disk_health.Columns(), smart_disk_health.GenerateFunc,
+			OnPlatforms("darwin", "linux", "windows"), RequiresAdmin()),
 
-		firefox_preferences.TablePlugin(osqueryLogger),
-		cryptoinfotable.TablePlugin(osqueryLogger),
+		PluginTable(firefox_preferences.TablePlugin(osqueryLogger)), // table name is "firefox_preferences"
+		PluginTable(cryptoinfotable.TablePlugin(osqueryLogger)),     // table name is "cryptoinfo"
 
 		// Additional data format tables
-		dataflattentable.TablePlugin(osqueryLogger, dataflattentable.JsonType),  // table name is "parse_json"
-		dataflattentable.TablePlugin(osqueryLogger, dataflattentable.JsonlType), // table name is "parse_jsonl"
-		dataflattentable.TablePlugin(osqueryLogger, dataflattentable.XmlType),   // table name is "parse_xml"
-		dataflattentable.TablePlugin(osqueryLogger, dataflattentable.IniType),   // table name is "parse_ini"
-
+		PluginTable(dataflattentable.TablePlugin(osqueryLogger, dataflattentable.JsonType)),  // table name is "parse_json"
+		PluginTable(dataflattentable.TablePlugin(osqueryLogger, dataflattentable.JsonlType)), // table name is "parse_jsonl"
+		PluginTable(dataflattentable.TablePlugin(osqueryLogger, dataflattentable.XmlType)),   // table name is "parse_xml"
+		PluginTable(dataflattentable.TablePlugin(osqueryLogger, dataflattentable.IniType)),   // table name is "parse_ini"
 	}
-	return plugins
 }
 
 // Interrupt shuts down the osquery manager server.
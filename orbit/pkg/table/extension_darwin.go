@@ -15,7 +15,9 @@ import (
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/firmware_eficheck_integrity_check"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/firmwarepasswd"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/ioreg"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/kernel_and_system_extensions"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/nvram_info"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/persistence_items"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/pmset"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/privaterelay"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/pwd_policy"
@@ -29,55 +31,57 @@ import (
 	"github.com/macadmins/osquery-extension/tables/mdm"
 	"github.com/macadmins/osquery-extension/tables/munki"
 	"github.com/macadmins/osquery-extension/tables/unifiedlog"
-
-	"github.com/osquery/osquery-go"
-	"github.com/osquery/osquery-go/plugin/table"
 )
 
-func PlatformTables() []osquery.OsqueryPlugin {
-	plugins := []osquery.OsqueryPlugin{
+// PlatformTables returns the extension tables of fleetd that are only
+// available on macOS.
+func PlatformTables() []TableSpec {
+	darwin := OnPlatforms("darwin")
+	specs := []TableSpec{
 		// Fleet tables
-		table.NewPlugin("icloud_private_relay", privaterelay.Columns(), privaterelay.Generate),
-		table.NewPlugin("user_login_settings", user_login_settings.Columns(), user_login_settings.Generate),
-		table.NewPlugin("pwd_policy", pwd_policy.Columns(), pwd_policy.Generate),
-		table.NewPlugin("csrutil_info", csrutil_info.Columns(), csrutil_info.Generate),
-		table.NewPlugin("nvram_info", nvram_info.Columns(), nvram_info.Generate),
-		table.NewPlugin("authdb", authdb.Columns(), authdb.Generate),
-		table.NewPlugin("pmset", pmset.Columns(), pmset.Generate),
-		table.NewPlugin("sudo_info", sudo_info.Columns(), sudo_info.Generate),
-		table.NewPlugin("software_update", software_update.Columns(), software_update.Generate),
-		table.NewPlugin("firmware_eficheck_integrity_check", firmware_eficheck_integrity_check.Columns(), firmware_eficheck_integrity_check.Generate),
-		table.NewPlugin("dscl", dscl.Columns(), dscl.Generate),
-		table.NewPlugin("apfs_volumes", apfs.VolumesColumns(), apfs.VolumesGenerate),
-		table.NewPlugin("apfs_physical_stores", apfs.PhysicalStoresColumns(), apfs.PhysicalStoresGenerate),
-		table.NewPlugin("corestorage_logical_volumes", corestorage.LogicalVolumesColumns(), corestorage.LogicalVolumesGenerate),
-		table.NewPlugin("corestorage_logical_volume_families", corestorage.LogicalVolumeFamiliesColumns(), corestorage.LogicalVolumeFamiliesGenerate),
-		table.NewPlugin("filevault_prk", filevault_prk.Columns(), filevault_prk.Generate),
-		table.NewPlugin("find_cmd", find_cmd.Columns(), find_cmd.Generate),
+		Table("icloud_private_relay", privaterelay.Columns(), privaterelay.Generate, darwin),
+		Table("user_login_settings", user_login_settings.Columns(), user_login_settings.Generate, darwin),
+		Table("pwd_policy", pwd_policy.Columns(), pwd_policy.Generate, darwin),
+		Table("csrutil_info", csrutil_info.Columns(), csrutil_info.Generate, darwin),
+		Table("nvram_info", nvram_info.Columns(), nvram_info.Generate, darwin),
+		Table("authdb", authdb.Columns(), authdb.Generate, darwin),
+		Table("pmset", pmset.Columns(), pmset.Generate, darwin),
+		Table("sudo_info", sudo_info.Columns(), sudo_info.Generate, darwin),
+		Table("software_update", software_update.Columns(), software_update.Generate, darwin),
+		Table("firmware_eficheck_integrity_check", firmware_eficheck_integrity_check.Columns(), firmware_eficheck_integrity_check.Generate, darwin),
+		Table("dscl", dscl.Columns(), dscl.Generate, darwin),
+		Table("apfs_volumes", apfs.VolumesColumns(), apfs.VolumesGenerate, darwin),
+		Table("apfs_physical_stores", apfs.PhysicalStoresColumns(), apfs.PhysicalStoresGenerate, darwin),
+		Table("corestorage_logical_volumes", corestorage.LogicalVolumesColumns(), corestorage.LogicalVolumesGenerate, darwin),
+		Table("corestorage_logical_volume_families", corestorage.LogicalVolumeFamiliesColumns(), corestorage.LogicalVolumeFamiliesGenerate, darwin),
+		Table("filevault_prk", filevault_prk.Columns(), filevault_prk.Generate, darwin, RequiresAdmin()),
+		Table("find_cmd", find_cmd.Columns(), find_cmd.Generate, darwin),
+		Table("kernel_extensions_and_system_extensions", kernel_and_system_extensions.Columns(), kernel_and_system_extensions.Generate, darwin, RequiresAdmin()),
+		Table("login_items_and_launch_agents", persistence_items.Columns(), persistence_items.Generate, darwin, RequiresAdmin()),
 
 		// Macadmins extension tables
-		table.NewPlugin("filevault_users", filevaultusers.FileVaultUsersColumns(), filevaultusers.FileVaultUsersGenerate),
-		table.NewPlugin("macos_profiles", macos_profiles.MacOSProfilesColumns(), macos_profiles.MacOSProfilesGenerate),
-		table.NewPlugin("mdm", mdm.MDMInfoColumns(), mdm.MDMInfoGenerate),
-		table.NewPlugin("munki_info", munki.MunkiInfoColumns(), munki.MunkiInfoGenerate),
-		table.NewPlugin("munki_installs", munki.MunkiInstallsColumns(), munki.MunkiInstallsGenerate),
-		table.NewPlugin("macos_rsr", macosrsr.MacOSRsrColumns(), macosrsr.MacOSRsrGenerate),
+		Table("filevault_users", filevaultusers.FileVaultUsersColumns(), filevaultusers.FileVaultUsersGenerate, darwin),
+		Table("macos_profiles", macos_profiles.MacOSProfilesColumns(), macos_profiles.MacOSProfilesGenerate, darwin),
+		Table("mdm", mdm.MDMInfoColumns(), mdm.MDMInfoGenerate, darwin),
+		Table("munki_info", munki.MunkiInfoColumns(), munki.MunkiInfoGenerate, darwin),
+		Table("munki_installs", munki.MunkiInstallsColumns(), munki.MunkiInstallsGenerate, darwin),
+		Table("macos_rsr", macosrsr.MacOSRsrColumns(), macosrsr.MacOSRsrGenerate, darwin),
 		// osquery version 5.5.0 and up ships a unified_log table in core
 		// we are renaming the one from the macadmins extension to avoid collision
-		table.NewPlugin("macadmins_unified_log", unifiedlog.UnifiedLogColumns(), unifiedlog.UnifiedLogGenerate),
+		Table("macadmins_unified_log", unifiedlog.UnifiedLogColumns(), unifiedlog.UnifiedLogGenerate, darwin),
 
-		filevault_status.TablePlugin(osqueryLogger), // table name is "filevault_status"
-		ioreg.TablePlugin(osqueryLogger),            // table name is "ioreg"
+		PluginTable(filevault_status.TablePlugin(osqueryLogger), darwin), // table name is "filevault_status"
+		PluginTable(ioreg.TablePlugin(osqueryLogger), darwin),            // table name is "ioreg"
 
 		// firmwarepasswd table. Only returns valid data on a Mac with an Intel processor. Background: https://support.apple.com/en-us/HT204455
-		firmwarepasswd.TablePlugin(osqueryLogger), // table name is "firmwarepasswd"
+		PluginTable(firmwarepasswd.TablePlugin(osqueryLogger), darwin, RequiresAdmin()), // table name is "firmwarepasswd"
 
 		// Table for parsing Apple Property List files, which are typically stored in ~/Library/Preferences/
-		dataflattentable.TablePlugin(osqueryLogger, dataflattentable.PlistType), // table name is "parse_plist"
+		PluginTable(dataflattentable.TablePlugin(osqueryLogger, dataflattentable.PlistType), darwin), // table name is "parse_plist"
 	}
 
 	// append platform specific tables
-	plugins = appendTables(plugins)
+	specs = appendTables(specs)
 
-	return plugins
+	return specs
 }
@@ -2,9 +2,7 @@
 
 package table
 
-import "github.com/osquery/osquery-go"
-
 // stub for amd64 platforms
-func appendTables(plugins []osquery.OsqueryPlugin) []osquery.OsqueryPlugin {
-	return plugins
+func appendTables(specs []TableSpec) []TableSpec {
+	return specs
 }
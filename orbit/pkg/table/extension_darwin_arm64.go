@@ -5,14 +5,12 @@ package table
 import (
 	// ARM64 Kolide tables
 	appicons "github.com/fleetdm/fleet/v4/orbit/pkg/table/app-icons"
-
-	"github.com/osquery/osquery-go"
 )
 
-func appendTables(plugins []osquery.OsqueryPlugin) []osquery.OsqueryPlugin {
-	plugins = append(plugins,
+func appendTables(specs []TableSpec) []TableSpec {
+	specs = append(specs,
 		// arm64 tables
-		appicons.AppIcons(),
+		PluginTable(appicons.AppIcons(), OnPlatforms("darwin")), // table name is "app_icons"
 	)
-	return plugins
+	return specs
 }
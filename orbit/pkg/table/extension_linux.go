@@ -6,14 +6,15 @@ import (
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/crowdstrike/falcon_kernel_check"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/crowdstrike/falconctl"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/cryptsetup"
-
-	"github.com/osquery/osquery-go"
 )
 
-func PlatformTables() []osquery.OsqueryPlugin {
-	return []osquery.OsqueryPlugin{
-		cryptsetup.TablePlugin(osqueryLogger),            // table name is "cryptsetup_status"
-		falconctl.NewFalconctlOptionTable(osqueryLogger), // table name is "falconctl_option"
-		falcon_kernel_check.TablePlugin(osqueryLogger),   // table name is "falcon_kernel_check"
+// PlatformTables returns the extension tables of fleetd that are only
+// available on Linux.
+func PlatformTables() []TableSpec {
+	linux := OnPlatforms("linux")
+	return []TableSpec{
+		PluginTable(cryptsetup.TablePlugin(osqueryLogger), linux, RequiresAdmin()),            // table name is "cryptsetup_status"
+		PluginTable(falconctl.NewFalconctlOptionTable(osqueryLogger), linux, RequiresAdmin()), // table name is "falconctl_options"
+		PluginTable(falcon_kernel_check.TablePlugin(osqueryLogger), linux),                    // table name is "falcon_kernel_check"
 	}
 }
@@ -5,6 +5,4 @@
 
 package table
 
-import "github.com/osquery/osquery-go"
-
-func PlatformTables() []osquery.OsqueryPlugin { return nil }
+func PlatformTables() []TableSpec { return nil }
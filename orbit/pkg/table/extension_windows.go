@@ -5,18 +5,20 @@ package table
 import (
 	cisaudit "github.com/fleetdm/fleet/v4/orbit/pkg/table/cis_audit"
 	mdmbridge "github.com/fleetdm/fleet/v4/orbit/pkg/table/mdm"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/windows_services_acl"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table/windowsupdatetable"
-
-	"github.com/osquery/osquery-go"
-	"github.com/osquery/osquery-go/plugin/table"
 )
 
-func PlatformTables() []osquery.OsqueryPlugin {
-	return []osquery.OsqueryPlugin{
+// PlatformTables returns the extension tables of fleetd that are only
+// available on Windows.
+func PlatformTables() []TableSpec {
+	windows := OnPlatforms("windows")
+	return []TableSpec{
 		// Fleet tables
-		table.NewPlugin("mdm_bridge", mdmbridge.Columns(), mdmbridge.Generate),
-		table.NewPlugin("cis_audit", cisaudit.Columns(), cisaudit.Generate),
+		Table("mdm_bridge", mdmbridge.Columns(), mdmbridge.Generate, windows, RequiresAdmin()),
+		Table("cis_audit", cisaudit.Columns(), cisaudit.Generate, windows, RequiresAdmin()),
+		Table("windows_services_acl", windows_services_acl.Columns(), windows_services_acl.Generate, windows),
 
-		windowsupdatetable.TablePlugin(windowsupdatetable.UpdatesTable, osqueryLogger), // table name is "windows_updates"
+		PluginTable(windowsupdatetable.TablePlugin(windowsupdatetable.UpdatesTable, osqueryLogger), windows), // table name is "windows_updates"
 	}
 }
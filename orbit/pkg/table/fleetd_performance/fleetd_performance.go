@@ -0,0 +1,103 @@
+// Package fleetd_performance implements the fleetd_performance table, that
+// reports the CPU and memory usage of the fleetd components (orbit, osqueryd
+// and Fleet Desktop) so that performance issues of the agent can be diagnosed
+// remotely.
+package fleetd_performance
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+	gopsutil_process "github.com/shirou/gopsutil/v3/process"
+)
+
+// components maps the process names (without the ".exe" extension on
+// Windows) to the fleetd component they run.
+var components = map[string]string{
+	"orbit":                     "orbit",
+	constant.OsquerydName:       "osqueryd",
+	constant.DesktopAppExecName: "fleet-desktop",
+}
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("component"),
+		table.IntegerColumn("pid"),
+		table.IntegerColumn("parent"),
+		table.DoubleColumn("cpu_percent"),
+		table.BigIntColumn("user_time"),
+		table.BigIntColumn("system_time"),
+		table.BigIntColumn("resident_size"),
+		table.IntegerColumn("threads"),
+		table.BigIntColumn("start_time"),
+	}
+}
+
+// GenerateFunc is called to return the results for the table at query time.
+// There is one row for each running process of a fleetd component.
+func GenerateFunc(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	processes, err := gopsutil_process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	for _, p := range processes {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			// No need to log errors here as this fails for some system processes
+			continue
+		}
+		component, ok := components[strings.TrimSuffix(strings.ToLower(name), ".exe")]
+		if !ok {
+			continue
+		}
+
+		row, err := processRow(ctx, p)
+		if err != nil {
+			// the process may have exited since it was listed
+			log.Debug().Err(err).Int32("pid", p.Pid).Msg("fleetd_performance: get process stats")
+			continue
+		}
+		row["component"] = component
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func processRow(ctx context.Context, p *gopsutil_process.Process) (map[string]string, error) {
+	// CPUPercent is the average CPU usage since the process started.
+	cpuPercent, err := p.CPUPercentWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	times, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the following are informational, do not fail the row if they cannot be
+	// read.
+	parent, _ := p.PpidWithContext(ctx)
+	threads, _ := p.NumThreadsWithContext(ctx)
+	createTime, _ := p.CreateTimeWithContext(ctx)
+
+	return map[string]string{
+		"pid":           strconv.FormatInt(int64(p.Pid), 10),
+		"parent":        strconv.FormatInt(int64(parent), 10),
+		"cpu_percent":   strconv.FormatFloat(cpuPercent, 'f', 2, 64),
+		"user_time":     strconv.FormatInt(int64(times.User*1000), 10),
+		"system_time":   strconv.FormatInt(int64(times.System*1000), 10),
+		"resident_size": strconv.FormatUint(mem.RSS, 10),
+		"threads":       strconv.FormatInt(int64(threads), 10),
+		"start_time":    strconv.FormatInt(createTime/1000, 10),
+	}, nil
+}
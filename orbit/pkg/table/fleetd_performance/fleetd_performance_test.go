@@ -0,0 +1,50 @@
+package fleetd_performance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	gopsutil_process "github.com/shirou/gopsutil/v3/process"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFunc(t *testing.T) {
+	ctx := context.Background()
+
+	// report the test process as if it was orbit
+	self, err := gopsutil_process.NewProcess(int32(os.Getpid()))
+	require.NoError(t, err)
+	name, err := self.Name()
+	require.NoError(t, err)
+	name = strings.TrimSuffix(strings.ToLower(filepath.Base(name)), ".exe")
+
+	old := components
+	components = map[string]string{name: "orbit"}
+	t.Cleanup(func() { components = old })
+
+	rows, err := GenerateFunc(ctx, table.QueryContext{})
+	require.NoError(t, err)
+
+	var found map[string]string
+	for _, row := range rows {
+		require.Equal(t, "orbit", row["component"])
+		if row["pid"] == strconv.Itoa(os.Getpid()) {
+			found = row
+		}
+	}
+	require.NotNil(t, found)
+	require.Equal(t, strconv.Itoa(os.Getppid()), found["parent"])
+	rss, err := strconv.ParseUint(found["resident_size"], 10, 64)
+	require.NoError(t, err)
+	require.NotZero(t, rss)
+	_, err = strconv.ParseFloat(found["cpu_percent"], 64)
+	require.NoError(t, err)
+	for _, col := range Columns() {
+		require.Contains(t, found, col.Name)
+	}
+}
@@ -0,0 +1,153 @@
+// Package fleetd_table_stats implements the fleetd_table_stats table, that
+// reports whether each of the extension tables of fleetd is enabled and how
+// many times and for how long it has been queried, so that slow extension
+// tables can be diagnosed remotely.
+package fleetd_table_stats
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// Collector collects the per-table statistics reported by the table. It is
+// safe for concurrent use.
+type Collector struct {
+	mu     sync.Mutex
+	tables map[string]*tableStats
+}
+
+type tableStats struct {
+	enabled        bool
+	disabledReason string
+	queries        uint64
+	errors         uint64
+	rows           uint64
+	totalDuration  time.Duration
+	maxDuration    time.Duration
+	lastDuration   time.Duration
+	lastQueriedAt  time.Time
+}
+
+// NewCollector returns an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{tables: make(map[string]*tableStats)}
+}
+
+// get returns the stats of the table, creating them if needed. The caller
+// must hold c.mu.
+func (c *Collector) get(name string) *tableStats {
+	ts := c.tables[name]
+	if ts == nil {
+		ts = &tableStats{}
+		c.tables[name] = ts
+	}
+	return ts
+}
+
+// SetStatus records whether the table is enabled, and if it is not, the
+// reason why.
+func (c *Collector) SetStatus(name string, enabled bool, disabledReason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ts := c.get(name)
+	ts.enabled = enabled
+	ts.disabledReason = disabledReason
+	if enabled {
+		ts.disabledReason = ""
+	}
+}
+
+// Record records a query of the table that started at the provided time and
+// took duration to return rows rows, failed indicates whether the query
+// returned an error.
+func (c *Collector) Record(name string, startedAt time.Time, duration time.Duration, rows int, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ts := c.get(name)
+	ts.queries++
+	if failed {
+		ts.errors++
+	}
+	ts.rows += uint64(rows)
+	ts.totalDuration += duration
+	if duration > ts.maxDuration {
+		ts.maxDuration = duration
+	}
+	ts.lastDuration = duration
+	ts.lastQueriedAt = startedAt
+}
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.IntegerColumn("enabled"),
+		table.TextColumn("disabled_reason"),
+		table.BigIntColumn("queries"),
+		table.BigIntColumn("errors"),
+		table.BigIntColumn("rows"),
+		table.DoubleColumn("total_duration_ms"),
+		table.DoubleColumn("avg_duration_ms"),
+		table.DoubleColumn("max_duration_ms"),
+		table.DoubleColumn("last_duration_ms"),
+		table.BigIntColumn("last_queried_at"),
+	}
+}
+
+// GenerateFunc is called to return the results for the table at query time.
+// There is one row for each extension table known by the collector, sorted by
+// name.
+func (c *Collector) GenerateFunc(_ context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.tables))
+	for name := range c.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		ts := c.tables[name]
+
+		var avg time.Duration
+		if ts.queries > 0 {
+			avg = ts.totalDuration / time.Duration(ts.queries)
+		}
+		var lastQueriedAt int64
+		if !ts.lastQueriedAt.IsZero() {
+			lastQueriedAt = ts.lastQueriedAt.Unix()
+		}
+		enabled := "0"
+		if ts.enabled {
+			enabled = "1"
+		}
+
+		rows = append(rows, map[string]string{
+			"name":              name,
+			"enabled":           enabled,
+			"disabled_reason":   ts.disabledReason,
+			"queries":           strconv.FormatUint(ts.queries, 10),
+			"errors":            strconv.FormatUint(ts.errors, 10),
+			"rows":              strconv.FormatUint(ts.rows, 10),
+			"total_duration_ms": formatMilliseconds(ts.totalDuration),
+			"avg_duration_ms":   formatMilliseconds(avg),
+			"max_duration_ms":   formatMilliseconds(ts.maxDuration),
+			"last_duration_ms":  formatMilliseconds(ts.lastDuration),
+			"last_queried_at":   strconv.FormatInt(lastQueriedAt, 10),
+		})
+	}
+	return rows, nil
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
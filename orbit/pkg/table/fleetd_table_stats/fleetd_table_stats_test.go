@@ -0,0 +1,69 @@
+package fleetd_table_stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFunc(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector()
+
+	rows, err := c.GenerateFunc(ctx, table.QueryContext{})
+	require.NoError(t, err)
+	require.Empty(t, rows)
+
+	now := time.Unix(1700000000, 0)
+	c.SetStatus("b_table", true, "")
+	c.SetStatus("a_table", false, "requires administrator privileges")
+	c.Record("b_table", now.Add(-time.Minute), 10*time.Millisecond, 3, false)
+	c.Record("b_table", now, 30*time.Millisecond, 0, true)
+	c.Record("b_table", now.Add(time.Minute), 2*time.Millisecond, 5, false)
+
+	rows, err = c.GenerateFunc(ctx, table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		for _, col := range Columns() {
+			require.Contains(t, row, col.Name)
+		}
+	}
+
+	require.Equal(t, map[string]string{
+		"name":              "a_table",
+		"enabled":           "0",
+		"disabled_reason":   "requires administrator privileges",
+		"queries":           "0",
+		"errors":            "0",
+		"rows":              "0",
+		"total_duration_ms": "0.000",
+		"avg_duration_ms":   "0.000",
+		"max_duration_ms":   "0.000",
+		"last_duration_ms":  "0.000",
+		"last_queried_at":   "0",
+	}, rows[0])
+	require.Equal(t, map[string]string{
+		"name":              "b_table",
+		"enabled":           "1",
+		"disabled_reason":   "",
+		"queries":           "3",
+		"errors":            "1",
+		"rows":              "8",
+		"total_duration_ms": "42.000",
+		"avg_duration_ms":   "14.000",
+		"max_duration_ms":   "30.000",
+		"last_duration_ms":  "2.000",
+		"last_queried_at":   "1700000060",
+	}, rows[1])
+
+	// enabling a table clears the disabled reason
+	c.SetStatus("a_table", true, "ignored")
+	rows, err = c.GenerateFunc(ctx, table.QueryContext{})
+	require.NoError(t, err)
+	require.Equal(t, "1", rows[0]["enabled"])
+	require.Empty(t, rows[0]["disabled_reason"])
+}
@@ -0,0 +1,321 @@
+// Package kernel_and_system_extensions implements the
+// kernel_extensions_and_system_extensions table for macOS, that lists the
+// loaded kernel extensions and the installed system extensions along with
+// their team ID and how they were approved (by an MDM profile or by the
+// user), so that policies can verify that only approved extensions run.
+//
+// The parsing of the sources is kept platform independent so that it can be
+// tested on all platforms, only the collection is darwin specific.
+package kernel_and_system_extensions
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"howett.net/plist"
+)
+
+// Values of the type column.
+const (
+	typeKernelExtension = "kernel_extension"
+	typeSystemExtension = "system_extension"
+)
+
+// Values of the approval_source column.
+const (
+	approvalApple = "apple"
+	approvalMDM   = "mdm"
+	approvalUser  = "user"
+)
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("type"),
+		table.TextColumn("identifier"),
+		table.TextColumn("version"),
+		table.TextColumn("team_id"),
+		table.TextColumn("category"),
+		table.TextColumn("state"),
+		table.IntegerColumn("approved"),
+		table.TextColumn("approval_source"),
+	}
+}
+
+// extension is a row of the table.
+type extension struct {
+	typ            string
+	identifier     string
+	version        string
+	teamID         string
+	category       string
+	state          string
+	approvalSource string
+}
+
+func (e extension) row() map[string]string {
+	approved := "0"
+	if e.approvalSource != "" {
+		approved = "1"
+	}
+	return map[string]string{
+		"type":            e.typ,
+		"identifier":      e.identifier,
+		"version":         e.version,
+		"team_id":         e.teamID,
+		"category":        e.category,
+		"state":           e.state,
+		"approved":        approved,
+		"approval_source": e.approvalSource,
+	}
+}
+
+// loadedKext is a kernel extension loaded in the kernel.
+type loadedKext struct {
+	identifier string
+	version    string
+}
+
+// kmutilLoadedRegexp matches the "bundle_id (version) UUID" part of the lines
+// output by "kmutil showloaded --list-only".
+var kmutilLoadedRegexp = regexp.MustCompile(`\s(\S+) \(([^)]*)\) [0-9A-Fa-f]{8}-[0-9A-Fa-f-]{27}\s`)
+
+// parseKmutilShowloaded parses the output of "kmutil showloaded --list-only".
+func parseKmutilShowloaded(out []byte) []loadedKext {
+	var kexts []loadedKext
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		m := kmutilLoadedRegexp.FindStringSubmatch(sc.Text() + " ")
+		if m == nil {
+			// header or informational line
+			continue
+		}
+		kexts = append(kexts, loadedKext{identifier: m[1], version: m[2]})
+	}
+	return kexts
+}
+
+// kextPolicy is a row of the kext_policy table of the KextPolicy database,
+// that holds the kernel extensions approved (or denied) by the user.
+type kextPolicy struct {
+	teamID   string
+	bundleID string
+	allowed  bool
+}
+
+// parseKextPolicy parses the output of "sqlite3" for the query
+// "SELECT team_id, bundle_id, allowed FROM kext_policy", with the default
+// "|" separator.
+func parseKextPolicy(out []byte) []kextPolicy {
+	var policies []kextPolicy
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		parts := strings.Split(strings.TrimSpace(sc.Text()), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		policies = append(policies, kextPolicy{teamID: parts[0], bundleID: parts[1], allowed: parts[2] == "1"})
+	}
+	return policies
+}
+
+// parseKextTeamIDs parses the output of "sqlite3" for the query
+// "SELECT bundle_id, team_id FROM kext_load_history_v3", with the default
+// "|" separator, and returns the team ID of each bundle ID.
+func parseKextTeamIDs(out []byte) map[string]string {
+	teamIDs := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		parts := strings.Split(strings.TrimSpace(sc.Text()), "|")
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		teamIDs[parts[0]] = parts[1]
+	}
+	return teamIDs
+}
+
+// mdmPolicy is the set of extensions allowed by MDM profiles, either as a
+// whole team or by bundle ID.
+type mdmPolicy struct {
+	teamIDs    map[string]bool
+	extensions map[string]map[string]bool // team ID -> bundle IDs
+}
+
+func (p *mdmPolicy) add(teamIDs []string, extensions map[string][]string) {
+	if p.teamIDs == nil {
+		p.teamIDs = make(map[string]bool)
+		p.extensions = make(map[string]map[string]bool)
+	}
+	for _, t := range teamIDs {
+		p.teamIDs[t] = true
+	}
+	for t, ids := range extensions {
+		if p.extensions[t] == nil {
+			p.extensions[t] = make(map[string]bool)
+		}
+		for _, id := range ids {
+			p.extensions[t][id] = true
+		}
+	}
+}
+
+func (p mdmPolicy) allows(teamID, identifier string) bool {
+	if teamID == "" {
+		return false
+	}
+	return p.teamIDs[teamID] || p.extensions[teamID][identifier]
+}
+
+// parseKextMDMPolicy parses the managed preferences of the
+// com.apple.syspolicy.kernel-extension-policy MDM payload.
+func parseKextMDMPolicy(b []byte) (mdmPolicy, error) {
+	var prefs struct {
+		AllowedTeamIdentifiers  []string            `plist:"AllowedTeamIdentifiers"`
+		AllowedKernelExtensions map[string][]string `plist:"AllowedKernelExtensions"`
+	}
+	if _, err := plist.Unmarshal(b, &prefs); err != nil {
+		return mdmPolicy{}, fmt.Errorf("unmarshal kernel extension policy: %w", err)
+	}
+	var p mdmPolicy
+	p.add(prefs.AllowedTeamIdentifiers, prefs.AllowedKernelExtensions)
+	return p, nil
+}
+
+// systemExtension is an extension of the system extensions database.
+type systemExtension struct {
+	identifier string
+	version    string
+	teamID     string
+	categories []string
+	state      string
+}
+
+// parseSystemExtensionsDB parses /Library/SystemExtensions/db.plist and
+// returns the system extensions and the policy of the MDM profiles.
+func parseSystemExtensionsDB(b []byte) ([]systemExtension, mdmPolicy, error) {
+	var db struct {
+		Extensions []struct {
+			Identifier    string   `plist:"identifier"`
+			TeamID        string   `plist:"teamID"`
+			Categories    []string `plist:"categories"`
+			State         string   `plist:"state"`
+			BundleVersion struct {
+				ShortVersion string `plist:"CFBundleShortVersionString"`
+				Version      string `plist:"CFBundleVersion"`
+			} `plist:"bundleVersion"`
+		} `plist:"extensions"`
+		ExtensionPolicies []struct {
+			AllowedTeamIDs    []string            `plist:"allowedTeamIDs"`
+			AllowedExtensions map[string][]string `plist:"allowedExtensions"`
+		} `plist:"extensionPolicies"`
+	}
+	if _, err := plist.Unmarshal(b, &db); err != nil {
+		return nil, mdmPolicy{}, fmt.Errorf("unmarshal system extensions database: %w", err)
+	}
+
+	var policy mdmPolicy
+	for _, p := range db.ExtensionPolicies {
+		policy.add(p.AllowedTeamIDs, p.AllowedExtensions)
+	}
+
+	exts := make([]systemExtension, 0, len(db.Extensions))
+	for _, e := range db.Extensions {
+		version := e.BundleVersion.ShortVersion
+		if version == "" {
+			version = e.BundleVersion.Version
+		}
+		exts = append(exts, systemExtension{
+			identifier: e.Identifier,
+			version:    version,
+			teamID:     e.TeamID,
+			categories: e.Categories,
+			state:      e.State,
+		})
+	}
+	return exts, policy, nil
+}
+
+// systemExtensionCategory returns the short name of the category of a
+// system extension, e.g. "network_extension" for
+// "com.apple.system_extension.network_extension".
+func systemExtensionCategory(categories []string) string {
+	short := make([]string, 0, len(categories))
+	for _, c := range categories {
+		short = append(short, strings.TrimPrefix(c, "com.apple.system_extension."))
+	}
+	sort.Strings(short)
+	return strings.Join(short, ",")
+}
+
+// sources holds the data collected to generate the table.
+type sources struct {
+	loadedKexts      []loadedKext
+	kextTeamIDs      map[string]string
+	kextUserPolicies []kextPolicy
+	kextMDMPolicy    mdmPolicy
+	systemExtensions []systemExtension
+	sysextMDMPolicy  mdmPolicy
+}
+
+// extensions returns the rows of the table for the collected data.
+func (s sources) extensions() []extension {
+	var res []extension
+	for _, k := range s.loadedKexts {
+		e := extension{
+			typ:        typeKernelExtension,
+			identifier: k.identifier,
+			version:    k.version,
+			teamID:     s.kextTeamIDs[k.identifier],
+			state:      "loaded",
+		}
+		switch {
+		case strings.HasPrefix(k.identifier, "com.apple."):
+			// Apple's kernel extensions do not require approval.
+			e.approvalSource = approvalApple
+		case s.kextMDMPolicy.allows(e.teamID, e.identifier):
+			e.approvalSource = approvalMDM
+		case s.kextUserApproved(e.teamID, e.identifier):
+			e.approvalSource = approvalUser
+		}
+		res = append(res, e)
+	}
+
+	for _, se := range s.systemExtensions {
+		e := extension{
+			typ:        typeSystemExtension,
+			identifier: se.identifier,
+			version:    se.version,
+			teamID:     se.teamID,
+			category:   systemExtensionCategory(se.categories),
+			state:      se.state,
+		}
+		switch {
+		case s.sysextMDMPolicy.allows(e.teamID, e.identifier):
+			e.approvalSource = approvalMDM
+		case se.state == "activated_enabled" || se.state == "activated_disabled":
+			// the extension was activated without an MDM policy, so it was
+			// approved by the user.
+			e.approvalSource = approvalUser
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func (s sources) kextUserApproved(teamID, identifier string) bool {
+	for _, p := range s.kextUserPolicies {
+		if !p.allowed {
+			continue
+		}
+		if p.bundleID == identifier || (teamID != "" && p.teamID == teamID) {
+			return true
+		}
+	}
+	return false
+}
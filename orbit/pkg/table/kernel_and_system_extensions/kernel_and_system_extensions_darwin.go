@@ -0,0 +1,93 @@
+//go:build darwin
+// +build darwin
+
+package kernel_and_system_extensions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	kmutilPath  = "/usr/bin/kmutil"
+	sqlite3Path = "/usr/bin/sqlite3"
+
+	kextPolicyDBPath     = "/var/db/SystemPolicyConfiguration/KextPolicy"
+	kextPolicyQuery      = "SELECT team_id, bundle_id, allowed FROM kext_policy"
+	kextLoadHistoryQuery = "SELECT bundle_id, team_id FROM kext_load_history_v3"
+
+	kextMDMPolicyPath      = "/Library/Managed Preferences/com.apple.syspolicy.kernel-extension-policy.plist"
+	systemExtensionsDBPath = "/Library/SystemExtensions/db.plist"
+
+	commandTimeout = 30 * time.Second
+)
+
+// Generate is called to return the results for the table at query time.
+// There is one row for each loaded kernel extension and for each installed
+// system extension.
+func Generate(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	var s sources
+
+	out, err := runCommand(ctx, kmutilPath, "showloaded", "--list-only")
+	if err != nil {
+		return nil, fmt.Errorf("list loaded kernel extensions: %w", err)
+	}
+	s.loadedKexts = parseKmutilShowloaded(out)
+
+	// the KextPolicy database only exists if third-party kernel extensions
+	// were ever loaded or approved, so errors are not fatal.
+	if out, err := runCommand(ctx, sqlite3Path, "-readonly", kextPolicyDBPath, kextLoadHistoryQuery); err == nil {
+		s.kextTeamIDs = parseKextTeamIDs(out)
+	} else {
+		log.Debug().Err(err).Msg("kernel_extensions_and_system_extensions: read kext load history")
+	}
+	if out, err := runCommand(ctx, sqlite3Path, "-readonly", kextPolicyDBPath, kextPolicyQuery); err == nil {
+		s.kextUserPolicies = parseKextPolicy(out)
+	} else {
+		log.Debug().Err(err).Msg("kernel_extensions_and_system_extensions: read kext policy")
+	}
+
+	switch b, err := os.ReadFile(kextMDMPolicyPath); {
+	case err == nil:
+		if s.kextMDMPolicy, err = parseKextMDMPolicy(b); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("read kernel extension policy: %w", err)
+	}
+
+	switch b, err := os.ReadFile(systemExtensionsDBPath); {
+	case err == nil:
+		if s.systemExtensions, s.sysextMDMPolicy, err = parseSystemExtensionsDB(b); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("read system extensions database: %w", err)
+	}
+
+	exts := s.extensions()
+	rows := make([]map[string]string, 0, len(exts))
+	for _, e := range exts {
+		rows = append(rows, e.row())
+	}
+	return rows, nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %w", name, err)
+	}
+	return out, nil
+}
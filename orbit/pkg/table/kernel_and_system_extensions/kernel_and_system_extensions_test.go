@@ -0,0 +1,86 @@
+package kernel_and_system_extensions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return b
+}
+
+func TestParseKmutilShowloaded(t *testing.T) {
+	kexts := parseKmutilShowloaded(readTestdata(t, "kmutil_showloaded.txt"))
+	require.Equal(t, []loadedKext{
+		{identifier: "com.apple.kpi.bsd", version: "22.6.0"},
+		{identifier: "com.example.driver.Widget", version: "1.2.3"},
+		{identifier: "com.acme.kext.Monitor", version: "4.0"},
+		{identifier: "org.unknown.Rogue", version: "0.1"},
+	}, kexts)
+
+	require.Empty(t, parseKmutilShowloaded(nil))
+}
+
+func TestExtensions(t *testing.T) {
+	var s sources
+	var err error
+	s.loadedKexts = parseKmutilShowloaded(readTestdata(t, "kmutil_showloaded.txt"))
+	s.kextTeamIDs = parseKextTeamIDs(readTestdata(t, "kext_load_history.txt"))
+	s.kextUserPolicies = parseKextPolicy(readTestdata(t, "kext_policy.txt"))
+	s.kextMDMPolicy, err = parseKextMDMPolicy(readTestdata(t, "kext_mdm_policy.plist"))
+	require.NoError(t, err)
+	s.systemExtensions, s.sysextMDMPolicy, err = parseSystemExtensionsDB(readTestdata(t, "system_extensions_db.plist"))
+	require.NoError(t, err)
+
+	var rows []map[string]string
+	for _, e := range s.extensions() {
+		rows = append(rows, e.row())
+	}
+
+	kext := func(identifier, version, teamID, approved, source string) map[string]string {
+		return map[string]string{
+			"type":            "kernel_extension",
+			"identifier":      identifier,
+			"version":         version,
+			"team_id":         teamID,
+			"category":        "",
+			"state":           "loaded",
+			"approved":        approved,
+			"approval_source": source,
+		}
+	}
+	sysext := func(identifier, version, teamID, category, state, approved, source string) map[string]string {
+		return map[string]string{
+			"type":            "system_extension",
+			"identifier":      identifier,
+			"version":         version,
+			"team_id":         teamID,
+			"category":        category,
+			"state":           state,
+			"approved":        approved,
+			"approval_source": source,
+		}
+	}
+	require.Equal(t, []map[string]string{
+		kext("com.apple.kpi.bsd", "22.6.0", "", "1", "apple"),
+		kext("com.example.driver.Widget", "1.2.3", "EXAMPLE123", "1", "mdm"),
+		kext("com.acme.kext.Monitor", "4.0", "ACME456789", "1", "user"),
+		kext("org.unknown.Rogue", "0.1", "", "0", ""),
+		sysext("com.edr.agent.extension", "7.1", "EDRTEAM456", "endpoint_security", "activated_enabled", "1", "mdm"),
+		sysext("com.vpn.client.tunnel", "2.0", "VPNTEAM123", "network_extension", "activated_enabled", "1", "mdm"),
+		sysext("com.filter.app.extension", "15", "FILTER7890", "network_extension", "activated_enabled", "1", "user"),
+		sysext("com.pending.driver", "1.0", "PENDING000", "driver_extension", "activated_waiting_for_user", "0", ""),
+	}, rows)
+}
+
+func TestParseInvalidPlists(t *testing.T) {
+	_, err := parseKextMDMPolicy([]byte("not a plist"))
+	require.Error(t, err)
+	_, _, err = parseSystemExtensionsDB([]byte("not a plist"))
+	require.Error(t, err)
+}
@@ -60,6 +60,7 @@ func (o Extension) Columns() []table.ColumnDefinition {
 		table.TextColumn("desktop_version"),
 		table.BigIntColumn("uptime"),
 		table.IntegerColumn("scripts_enabled"),
+		table.TextColumn("timezone"),
 	}
 }
 
@@ -104,5 +105,6 @@ func (o Extension) GenerateFunc(_ context.Context, _ table.QueryContext) ([]map[
 		"desktop_version":     o.dektopVersion,
 		"uptime":              strconv.FormatInt(int64(time.Since(o.startTime).Seconds()), 10),
 		"scripts_enabled":     strconv.FormatInt(boolToInt(o.scriptsEnabled()), 10),
+		"timezone":            localTimezone(),
 	}}, nil
 }
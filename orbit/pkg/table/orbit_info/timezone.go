@@ -0,0 +1,20 @@
+package orbit_info
+
+import "strings"
+
+// ianaTimezoneFromPath returns the IANA name of the timezone from the path of
+// a zoneinfo file, e.g. "America/New_York" for
+// "/usr/share/zoneinfo/America/New_York". It returns an empty string if the
+// path is not in a zoneinfo directory.
+func ianaTimezoneFromPath(path string) string {
+	i := strings.LastIndex(path, "zoneinfo/")
+	if i < 0 {
+		return ""
+	}
+	name := path[i+len("zoneinfo/"):]
+	// some distributions have the zoneinfo files under the "posix" and "right"
+	// sub-directories, those are not part of the name.
+	name = strings.TrimPrefix(name, "posix/")
+	name = strings.TrimPrefix(name, "right/")
+	return name
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package orbit_info
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localTimezone returns the IANA name of the timezone configured on the host,
+// or an empty string if it cannot be determined.
+func localTimezone() string {
+	// /etc/localtime is a symlink to the zoneinfo file of the timezone on macOS
+	// and most Linux distributions.
+	if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if name := ianaTimezoneFromPath(target); name != "" {
+			return name
+		}
+	}
+	// Debian based distributions may also store the name in /etc/timezone.
+	if b, err := os.ReadFile("/etc/timezone"); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return ""
+}
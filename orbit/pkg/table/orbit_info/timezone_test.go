@@ -0,0 +1,24 @@
+package orbit_info
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIANATimezoneFromPath(t *testing.T) {
+	for _, c := range []struct {
+		path string
+		want string
+	}{
+		{"/usr/share/zoneinfo/America/New_York", "America/New_York"},
+		{"/var/db/timezone/zoneinfo/Europe/Paris", "Europe/Paris"},
+		{"/usr/share/zoneinfo/Etc/UTC", "Etc/UTC"},
+		{"/usr/share/zoneinfo/posix/Asia/Tokyo", "Asia/Tokyo"},
+		{"/usr/share/zoneinfo/right/UTC", "UTC"},
+		{"/etc/localtime", ""},
+		{"", ""},
+	} {
+		require.Equal(t, c.want, ianaTimezoneFromPath(c.path), c.path)
+	}
+}
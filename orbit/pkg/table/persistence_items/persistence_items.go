@@ -0,0 +1,373 @@
+// Package persistence_items implements the login_items_and_launch_agents
+// table for macOS, that aggregates the items that run automatically on the
+// host (login items, LaunchAgents, LaunchDaemons and the other items
+// registered with the Background Task Management framework) along with the
+// code signing information of their program, so that threat hunting queries
+// do not need to join several partial tables.
+//
+// The parsing of the sources is kept platform independent so that it can be
+// tested on all platforms, only the collection is darwin specific.
+package persistence_items
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"howett.net/plist"
+)
+
+// Values of the type column.
+const (
+	typeLaunchAgent    = "launch_agent"
+	typeLaunchDaemon   = "launch_daemon"
+	typeLoginItem      = "login_item"
+	typeBackgroundItem = "background_item"
+)
+
+// Values of the scope column.
+const (
+	scopeSystem = "system"
+	scopeUser   = "user"
+)
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("type"),
+		table.TextColumn("scope"),
+		table.TextColumn("uid"),
+		table.TextColumn("name"),
+		table.TextColumn("identifier"),
+		table.TextColumn("path"),
+		table.TextColumn("program"),
+		table.TextColumn("arguments"),
+		table.IntegerColumn("run_at_load"),
+		table.IntegerColumn("keep_alive"),
+		table.IntegerColumn("disabled"),
+		table.TextColumn("btm_disposition"),
+		table.IntegerColumn("btm_enabled"),
+		table.TextColumn("developer_name"),
+		table.IntegerColumn("signed"),
+		table.TextColumn("signing_identifier"),
+		table.TextColumn("signing_authority"),
+		table.TextColumn("team_id"),
+	}
+}
+
+// item is a row of the table.
+type item struct {
+	typ            string
+	scope          string
+	uid            string
+	name           string
+	identifier     string
+	path           string
+	program        string
+	arguments      string
+	runAtLoad      bool
+	keepAlive      bool
+	disabled       bool
+	btmDisposition string
+	developerName  string
+	teamID         string
+	signature      signature
+}
+
+func (it item) row() map[string]string {
+	boolStr := func(b bool) string {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	teamID := it.signature.teamID
+	if teamID == "" {
+		teamID = it.teamID
+	}
+	btmEnabled := ""
+	if it.btmDisposition != "" {
+		btmEnabled = boolStr(strings.Contains(", "+it.btmDisposition+",", " enabled,"))
+	}
+	return map[string]string{
+		"type":               it.typ,
+		"scope":              it.scope,
+		"uid":                it.uid,
+		"name":               it.name,
+		"identifier":         it.identifier,
+		"path":               it.path,
+		"program":            it.program,
+		"arguments":          it.arguments,
+		"run_at_load":        boolStr(it.runAtLoad),
+		"keep_alive":         boolStr(it.keepAlive),
+		"disabled":           boolStr(it.disabled),
+		"btm_disposition":    it.btmDisposition,
+		"btm_enabled":        btmEnabled,
+		"developer_name":     it.developerName,
+		"signed":             boolStr(it.signature.signed),
+		"signing_identifier": it.signature.identifier,
+		"signing_authority":  it.signature.authority,
+		"team_id":            teamID,
+	}
+}
+
+// signingTarget returns the path of the code whose signature is reported,
+// i.e. the program of the item or the bundle of the login items that have
+// none.
+func (it item) signingTarget() string {
+	if it.program != "" {
+		return it.program
+	}
+	if it.typ == typeLoginItem && strings.HasPrefix(it.path, "/") {
+		return it.path
+	}
+	return ""
+}
+
+// parseLaunchdPlist parses the property list of a LaunchAgent or
+// LaunchDaemon located at path.
+func parseLaunchdPlist(b []byte, typ, scope, uid, path string) (item, error) {
+	var p struct {
+		Label            string      `plist:"Label"`
+		Program          string      `plist:"Program"`
+		ProgramArguments []string    `plist:"ProgramArguments"`
+		RunAtLoad        bool        `plist:"RunAtLoad"`
+		KeepAlive        interface{} `plist:"KeepAlive"`
+		Disabled         bool        `plist:"Disabled"`
+	}
+	if _, err := plist.Unmarshal(b, &p); err != nil {
+		return item{}, fmt.Errorf("unmarshal launchd plist %s: %w", path, err)
+	}
+
+	program := p.Program
+	if program == "" && len(p.ProgramArguments) > 0 {
+		program = p.ProgramArguments[0]
+	}
+	// KeepAlive is either a boolean or a dictionary of conditions in which
+	// case the job is kept alive when they are met.
+	var keepAlive bool
+	switch v := p.KeepAlive.(type) {
+	case bool:
+		keepAlive = v
+	case map[string]interface{}:
+		keepAlive = true
+	}
+
+	return item{
+		typ:        typ,
+		scope:      scope,
+		uid:        uid,
+		name:       p.Label,
+		identifier: p.Label,
+		path:       path,
+		program:    program,
+		arguments:  strings.Join(p.ProgramArguments, " "),
+		runAtLoad:  p.RunAtLoad,
+		keepAlive:  keepAlive,
+		disabled:   p.Disabled,
+	}, nil
+}
+
+// btmRecord is a record of the Background Task Management database.
+type btmRecord struct {
+	uid              string
+	name             string
+	developerName    string
+	teamID           string
+	typ              string
+	disposition      string
+	identifier       string
+	bundleIdentifier string
+	url              string
+	executablePath   string
+}
+
+var (
+	btmUIDRegexp    = regexp.MustCompile(`^\s*Records for UID (-?\d+)\s*:`)
+	btmRecordRegexp = regexp.MustCompile(`^\s*#\d+:\s*$`)
+	btmFieldRegexp  = regexp.MustCompile(`^\s*([A-Za-z ]+?):\s(.*)$`)
+	// btmHexSuffixRegexp matches the raw value that follows the type and the
+	// disposition, e.g. "legacy agent (0x10008)".
+	btmHexSuffixRegexp = regexp.MustCompile(`\s*\(0x[0-9a-fA-F]+\)$`)
+)
+
+// parseSfltoolDumpbtm parses the output of "sfltool dumpbtm".
+func parseSfltoolDumpbtm(out []byte) []btmRecord {
+	var (
+		records []btmRecord
+		uid     string
+		current *btmRecord
+	)
+	flush := func() {
+		if current != nil {
+			records = append(records, *current)
+			current = nil
+		}
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if m := btmUIDRegexp.FindStringSubmatch(line); m != nil {
+			flush()
+			uid = m[1]
+			continue
+		}
+		if btmRecordRegexp.MatchString(line) {
+			flush()
+			current = &btmRecord{uid: uid}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		m := btmFieldRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		if value == "(null)" {
+			value = ""
+		}
+		switch m[1] {
+		case "Name":
+			current.name = value
+		case "Developer Name":
+			current.developerName = value
+		case "Team Identifier":
+			current.teamID = value
+		case "Type":
+			current.typ = btmHexSuffixRegexp.ReplaceAllString(value, "")
+		case "Disposition":
+			value = btmHexSuffixRegexp.ReplaceAllString(value, "")
+			current.disposition = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		case "Identifier":
+			current.identifier = value
+		case "Bundle Identifier":
+			current.bundleIdentifier = value
+		case "URL":
+			current.url = value
+		case "Executable Path":
+			current.executablePath = value
+		}
+	}
+	flush()
+	return records
+}
+
+// itemType returns the type of the row of the record, or "" if the record is
+// not reported (e.g. the records that group the items of a developer).
+func (r btmRecord) itemType() string {
+	switch r.typ {
+	case "app", "login item":
+		return typeLoginItem
+	case "agent", "legacy agent":
+		return typeLaunchAgent
+	case "daemon", "legacy daemon":
+		return typeLaunchDaemon
+	case "developer":
+		return ""
+	default:
+		return typeBackgroundItem
+	}
+}
+
+// path returns the file system path of the URL of the record.
+func (r btmRecord) path() string {
+	u, err := url.Parse(r.url)
+	if err != nil || u.Scheme != "file" {
+		return r.url
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// mergeBTMRecords adds the BTM records to the launchd items. The records of
+// LaunchAgents and LaunchDaemons whose property list is already in items
+// complete the existing item, the others are added as new items.
+func mergeBTMRecords(items []item, records []btmRecord) []item {
+	byPath := make(map[string]int, len(items))
+	for i, it := range items {
+		byPath[it.path] = i
+	}
+
+	for _, r := range records {
+		typ := r.itemType()
+		if typ == "" {
+			continue
+		}
+		path := r.path()
+		if i, ok := byPath[path]; ok {
+			it := &items[i]
+			if it.btmDisposition == "" {
+				it.btmDisposition = r.disposition
+				it.developerName = r.developerName
+				it.teamID = r.teamID
+			}
+			continue
+		}
+
+		scope := scopeUser
+		if uid, err := strconv.Atoi(r.uid); err == nil && uid <= 0 {
+			scope = scopeSystem
+		}
+		identifier := r.bundleIdentifier
+		if identifier == "" {
+			identifier = r.identifier
+		}
+		byPath[path] = len(items)
+		items = append(items, item{
+			typ:            typ,
+			scope:          scope,
+			uid:            r.uid,
+			name:           r.name,
+			identifier:     identifier,
+			path:           path,
+			program:        r.executablePath,
+			btmDisposition: r.disposition,
+			developerName:  r.developerName,
+			teamID:         r.teamID,
+		})
+	}
+	return items
+}
+
+// signature is the code signing information of a program.
+type signature struct {
+	signed     bool
+	identifier string
+	authority  string
+	teamID     string
+}
+
+// parseCodesign parses the output of "codesign -dv --verbose=2".
+func parseCodesign(out []byte) signature {
+	var sig signature
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Identifier":
+			sig.signed = true
+			sig.identifier = value
+		case "Authority":
+			// the first authority is the signing certificate, the others are
+			// its chain.
+			if sig.authority == "" {
+				sig.authority = value
+			}
+		case "TeamIdentifier":
+			if value != "not set" {
+				sig.teamID = value
+			}
+		}
+	}
+	return sig
+}
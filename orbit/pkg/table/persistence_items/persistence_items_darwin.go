@@ -0,0 +1,127 @@
+//go:build darwin
+// +build darwin
+
+package persistence_items
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+)
+
+const commandTimeout = 30 * time.Second
+
+// launchdDirs are the directories of the property lists of the system
+// LaunchAgents and LaunchDaemons. The ones of macOS (in /System) are not
+// reported.
+var launchdDirs = []struct {
+	dir string
+	typ string
+}{
+	{"/Library/LaunchAgents", typeLaunchAgent},
+	{"/Library/LaunchDaemons", typeLaunchDaemon},
+}
+
+// Generate is called to return the results for the table at query time.
+// There is one row for each LaunchAgent, LaunchDaemon, login item and other
+// background item.
+func Generate(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	var items []item
+	for _, d := range launchdDirs {
+		items = append(items, launchdItems(d.dir, d.typ, scopeSystem, "")...)
+	}
+
+	homes, err := filepath.Glob("/Users/*")
+	if err != nil {
+		return nil, err
+	}
+	for _, home := range homes {
+		base := filepath.Base(home)
+		if base == "Shared" || strings.HasPrefix(base, ".") {
+			continue
+		}
+		info, err := os.Stat(home)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		var uid string
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid = strconv.FormatUint(uint64(st.Uid), 10)
+		}
+		items = append(items, launchdItems(filepath.Join(home, "Library", "LaunchAgents"), typeLaunchAgent, scopeUser, uid)...)
+	}
+
+	// sfltool is only available on macOS 13+, where login items and
+	// background items are managed by the Background Task Management
+	// framework.
+	if out, err := runCommand(ctx, "/usr/bin/sfltool", "dumpbtm"); err == nil {
+		items = mergeBTMRecords(items, parseSfltoolDumpbtm(out))
+	} else {
+		log.Debug().Err(err).Msg("login_items_and_launch_agents: sfltool dumpbtm")
+	}
+
+	signatures := make(map[string]signature)
+	rows := make([]map[string]string, 0, len(items))
+	for _, it := range items {
+		if target := it.signingTarget(); target != "" {
+			sig, ok := signatures[target]
+			if !ok {
+				sig = codesign(ctx, target)
+				signatures[target] = sig
+			}
+			it.signature = sig
+		}
+		rows = append(rows, it.row())
+	}
+	return rows, nil
+}
+
+func launchdItems(dir, typ, scope, uid string) []item {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.plist"))
+	if err != nil {
+		return nil
+	}
+	var items []item
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			log.Debug().Err(err).Str("path", path).Msg("login_items_and_launch_agents: read launchd plist")
+			continue
+		}
+		it, err := parseLaunchdPlist(b, typ, scope, uid, path)
+		if err != nil {
+			log.Debug().Err(err).Msg("login_items_and_launch_agents: parse launchd plist")
+			continue
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// codesign returns the code signing information of the program, the
+// information of the enclosing bundle is returned for the executables of
+// application bundles.
+func codesign(ctx context.Context, program string) signature {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	// codesign writes the information to stderr and fails if the program is
+	// not signed, in which case the output has no identifier.
+	out, _ := exec.CommandContext(ctx, "/usr/bin/codesign", "-dv", "--verbose=2", program).CombinedOutput()
+	return parseCodesign(out)
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, name, args...).Output()
+}
@@ -0,0 +1,140 @@
+package persistence_items
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return b
+}
+
+func TestParseCodesign(t *testing.T) {
+	require.Equal(t, signature{
+		signed:     true,
+		identifier: "com.example.agent",
+		authority:  "Developer ID Application: Example, Inc. (EXAMPLE123)",
+		teamID:     "EXAMPLE123",
+	}, parseCodesign(readTestdata(t, "codesign_signed.txt")))
+
+	require.Equal(t, signature{
+		signed:     true,
+		identifier: "updater-55554944a0b1c2d3",
+	}, parseCodesign(readTestdata(t, "codesign_adhoc.txt")))
+
+	require.Equal(t, signature{}, parseCodesign([]byte("/tmp/foo: code object is not signed at all\n")))
+}
+
+func TestParseSfltoolDumpbtm(t *testing.T) {
+	records := parseSfltoolDumpbtm(readTestdata(t, "sfltool_dumpbtm.txt"))
+	require.Len(t, records, 4)
+	require.Equal(t, btmRecord{
+		uid:            "0",
+		name:           "com.evil.daemon",
+		developerName:  "",
+		typ:            "legacy daemon",
+		disposition:    "enabled, allowed, visible, notified",
+		identifier:     "16.com.evil.daemon",
+		url:            "file:///Library/LaunchDaemons/com.evil.daemon.plist",
+		executablePath: "/private/tmp/.hidden/updater",
+	}, records[0])
+	require.Equal(t, "developer", records[1].typ)
+	require.Equal(t, "501", records[1].uid)
+	require.Equal(t, "com.getdropbox.dropbox", records[3].bundleIdentifier)
+	require.Equal(t, "/Applications/Dropbox.app", records[3].path())
+
+	require.Empty(t, parseSfltoolDumpbtm(nil))
+}
+
+func TestItems(t *testing.T) {
+	agent, err := parseLaunchdPlist(readTestdata(t, "com.example.agent.plist"), typeLaunchAgent, scopeSystem, "",
+		"/Library/LaunchAgents/com.example.agent.plist")
+	require.NoError(t, err)
+	daemon, err := parseLaunchdPlist(readTestdata(t, "com.evil.daemon.plist"), typeLaunchDaemon, scopeSystem, "",
+		"/Library/LaunchDaemons/com.evil.daemon.plist")
+	require.NoError(t, err)
+
+	items := mergeBTMRecords([]item{agent, daemon}, parseSfltoolDumpbtm(readTestdata(t, "sfltool_dumpbtm.txt")))
+	require.Len(t, items, 3)
+	require.Equal(t, "/Applications/Example.app/Contents/MacOS/example-agent", items[0].signingTarget())
+	require.Equal(t, "/private/tmp/.hidden/updater", items[1].signingTarget())
+	require.Equal(t, "/Applications/Dropbox.app", items[2].signingTarget())
+
+	items[0].signature = parseCodesign(readTestdata(t, "codesign_signed.txt"))
+	items[1].signature = parseCodesign(readTestdata(t, "codesign_adhoc.txt"))
+
+	var rows []map[string]string
+	for _, it := range items {
+		rows = append(rows, it.row())
+	}
+	require.Equal(t, []map[string]string{
+		{
+			"type":               "launch_agent",
+			"scope":              "system",
+			"uid":                "",
+			"name":               "com.example.agent",
+			"identifier":         "com.example.agent",
+			"path":               "/Library/LaunchAgents/com.example.agent.plist",
+			"program":            "/Applications/Example.app/Contents/MacOS/example-agent",
+			"arguments":          "/Applications/Example.app/Contents/MacOS/example-agent --background",
+			"run_at_load":        "1",
+			"keep_alive":         "1",
+			"disabled":           "0",
+			"btm_disposition":    "disabled, allowed, visible, notified",
+			"btm_enabled":        "0",
+			"developer_name":     "Example, Inc.",
+			"signed":             "1",
+			"signing_identifier": "com.example.agent",
+			"signing_authority":  "Developer ID Application: Example, Inc. (EXAMPLE123)",
+			"team_id":            "EXAMPLE123",
+		},
+		{
+			"type":               "launch_daemon",
+			"scope":              "system",
+			"uid":                "",
+			"name":               "com.evil.daemon",
+			"identifier":         "com.evil.daemon",
+			"path":               "/Library/LaunchDaemons/com.evil.daemon.plist",
+			"program":            "/private/tmp/.hidden/updater",
+			"arguments":          "",
+			"run_at_load":        "0",
+			"keep_alive":         "1",
+			"disabled":           "1",
+			"btm_disposition":    "enabled, allowed, visible, notified",
+			"btm_enabled":        "1",
+			"developer_name":     "",
+			"signed":             "1",
+			"signing_identifier": "updater-55554944a0b1c2d3",
+			"signing_authority":  "",
+			"team_id":            "",
+		},
+		{
+			"type":               "login_item",
+			"scope":              "user",
+			"uid":                "501",
+			"name":               "Dropbox",
+			"identifier":         "com.getdropbox.dropbox",
+			"path":               "/Applications/Dropbox.app",
+			"program":            "",
+			"arguments":          "",
+			"run_at_load":        "0",
+			"keep_alive":         "0",
+			"disabled":           "0",
+			"btm_disposition":    "enabled, allowed, visible, notified",
+			"btm_enabled":        "1",
+			"developer_name":     "Dropbox, Inc.",
+			"signed":             "0",
+			"signing_identifier": "",
+			"signing_authority":  "",
+			"team_id":            "G7HH3F8CAK",
+		},
+	}, rows)
+
+	_, err = parseLaunchdPlist([]byte("not a plist"), typeLaunchAgent, scopeUser, "501", "/tmp/x.plist")
+	require.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package table
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/table/fleetd_table_stats"
+	"github.com/osquery/osquery-go"
+	osquery_gen "github.com/osquery/osquery-go/gen/osquery"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+)
+
+// Privilege is the privilege level required by a table to return results.
+type Privilege int
+
+const (
+	// PrivilegeNone is for tables that can be queried by any user.
+	PrivilegeNone Privilege = iota
+	// PrivilegeAdmin is for tables that require root on macOS and Linux and
+	// an elevated (e.g. SYSTEM) process on Windows.
+	PrivilegeAdmin
+)
+
+// TableSpec declares an extension table of fleetd.
+type TableSpec struct {
+	// Name is the name of the table.
+	Name string
+	// Platforms are the GOOS values of the platforms supported by the table,
+	// empty if the table is supported on all platforms.
+	Platforms []string
+	// Privilege is the privilege level required by the table.
+	Privilege Privilege
+	// DisabledByDefault is true if the table is not registered unless it is
+	// explicitly enabled.
+	DisabledByDefault bool
+	// Plugin is the osquery plugin that implements the table.
+	Plugin osquery.OsqueryPlugin
+}
+
+// SpecOpt allows configuring a TableSpec.
+type SpecOpt func(*TableSpec)
+
+// OnPlatforms restricts the table to the provided platforms (GOOS values).
+func OnPlatforms(platforms ...string) SpecOpt {
+	return func(s *TableSpec) {
+		s.Platforms = platforms
+	}
+}
+
+// RequiresAdmin marks the table as requiring administrator privileges.
+func RequiresAdmin() SpecOpt {
+	return func(s *TableSpec) {
+		s.Privilege = PrivilegeAdmin
+	}
+}
+
+// DisabledByDefault marks the table as disabled unless explicitly enabled.
+func DisabledByDefault() SpecOpt {
+	return func(s *TableSpec) {
+		s.DisabledByDefault = true
+	}
+}
+
+// Table returns the spec of a table implemented by the generate function.
+func Table(name string, columns []table.ColumnDefinition, generate table.GenerateFunc, opts ...SpecOpt) TableSpec {
+	return PluginTable(table.NewPlugin(name, columns, generate), opts...)
+}
+
+// PluginTable returns the spec of a table implemented by the plugin, the
+// name of the table is the name of the plugin.
+func PluginTable(plugin osquery.OsqueryPlugin, opts ...SpecOpt) TableSpec {
+	s := TableSpec{Name: plugin.Name(), Plugin: plugin}
+	for _, fn := range opts {
+		fn(&s)
+	}
+	return s
+}
+
+// supports returns true if the table is supported on the platform.
+func (s TableSpec) supports(goos string) bool {
+	if len(s.Platforms) == 0 {
+		return true
+	}
+	for _, p := range s.Platforms {
+		if p == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAdminPrivileges returns true if the current process has administrator
+// privileges. It is a variable so that it can be overridden in tests.
+var hasAdminPrivileges = processHasAdminPrivileges
+
+// Registry holds the extension tables of fleetd, decides which ones are
+// registered with osquery and collects per-table query statistics, exposed by
+// the fleetd_table_stats table.
+type Registry struct {
+	goos      string
+	specs     []TableSpec
+	overrides map[string]bool
+	stats     *fleetd_table_stats.Collector
+}
+
+// NewRegistry creates a registry that only contains the fleetd_table_stats
+// table.
+func NewRegistry() *Registry {
+	r := &Registry{
+		goos:      runtime.GOOS,
+		overrides: make(map[string]bool),
+		stats:     fleetd_table_stats.NewCollector(),
+	}
+	r.Register(Table("fleetd_table_stats", fleetd_table_stats.Columns(), r.stats.GenerateFunc))
+	return r
+}
+
+// Register adds the tables to the registry. If a table with the same name is
+// already registered, the new table is ignored.
+func (r *Registry) Register(specs ...TableSpec) {
+	for _, s := range specs {
+		if r.lookup(s.Name) != nil {
+			log.Error().Str("table", s.Name).Msg("table already registered, ignoring")
+			continue
+		}
+		r.specs = append(r.specs, s)
+	}
+}
+
+// SetEnabled enables or disables the table, overriding its default.
+func (r *Registry) SetEnabled(name string, enabled bool) {
+	r.overrides[name] = enabled
+}
+
+func (r *Registry) lookup(name string) *TableSpec {
+	for i := range r.specs {
+		if r.specs[i].Name == name {
+			return &r.specs[i]
+		}
+	}
+	return nil
+}
+
+// Plugins returns the plugins of the tables that must be registered with
+// osquery, i.e. tables that are supported on the current platform, enabled
+// and whose required privileges are met. Queries to the returned plugins are
+// recorded in the table statistics.
+func (r *Registry) Plugins() []osquery.OsqueryPlugin {
+	var plugins []osquery.OsqueryPlugin
+	for _, s := range r.specs {
+		if !s.supports(r.goos) {
+			continue
+		}
+
+		enabled, overridden := r.overrides[s.Name]
+		if !overridden {
+			enabled = !s.DisabledByDefault
+		}
+		var reason string
+		switch {
+		case !enabled && overridden:
+			reason = "disabled by configuration"
+		case !enabled:
+			reason = "disabled by default"
+		case s.Privilege == PrivilegeAdmin && !hasAdminPrivileges():
+			enabled = false
+			reason = "requires administrator privileges"
+		}
+		r.stats.SetStatus(s.Name, enabled, reason)
+		if !enabled {
+			log.Debug().Str("table", s.Name).Str("reason", reason).Msg("table not registered")
+			continue
+		}
+
+		plugins = append(plugins, &instrumentedPlugin{OsqueryPlugin: s.Plugin, stats: r.stats})
+	}
+	return plugins
+}
+
+// instrumentedPlugin wraps a table plugin to record its queries.
+type instrumentedPlugin struct {
+	osquery.OsqueryPlugin
+	stats *fleetd_table_stats.Collector
+}
+
+func (p *instrumentedPlugin) Call(ctx context.Context, request osquery_gen.ExtensionPluginRequest) osquery_gen.ExtensionResponse {
+	if request["action"] != "generate" {
+		return p.OsqueryPlugin.Call(ctx, request)
+	}
+
+	start := time.Now()
+	resp := p.OsqueryPlugin.Call(ctx, request)
+	failed := resp.Status == nil || resp.Status.Code != 0
+	p.stats.Record(p.Name(), start, time.Since(start), len(resp.Response), failed)
+	return resp
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package table
+
+import "os"
+
+// processHasAdminPrivileges returns true if the process runs as root.
+func processHasAdminPrivileges() bool {
+	return os.Geteuid() == 0
+}
@@ -0,0 +1,115 @@
+package table
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/osquery/osquery-go"
+	osquery_gen "github.com/osquery/osquery-go/gen/osquery"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryPlugins(t *testing.T) {
+	isAdmin := false
+	old := hasAdminPrivileges
+	hasAdminPrivileges = func() bool { return isAdmin }
+	t.Cleanup(func() { hasAdminPrivileges = old })
+
+	generate := func(context.Context, table.QueryContext) ([]map[string]string, error) { return nil, nil }
+	columns := []table.ColumnDefinition{table.TextColumn("a")}
+
+	newRegistry := func() *Registry {
+		r := NewRegistry()
+		r.goos = "linux"
+		r.Register(
+			Table("all_platforms", columns, generate),
+			Table("linux_only", columns, generate, OnPlatforms("linux")),
+			Table("darwin_only", columns, generate, OnPlatforms("darwin", "windows")),
+			Table("admin_only", columns, generate, RequiresAdmin()),
+			Table("off_by_default", columns, generate, DisabledByDefault()),
+			// duplicates are ignored
+			Table("all_platforms", columns, generate, DisabledByDefault()),
+		)
+		return r
+	}
+	pluginNames := func(plugins []osquery.OsqueryPlugin) []string {
+		var names []string
+		for _, p := range plugins {
+			names = append(names, p.Name())
+		}
+		return names
+	}
+	statuses := func(r *Registry) map[string]string {
+		rows, err := r.stats.GenerateFunc(context.Background(), table.QueryContext{})
+		require.NoError(t, err)
+		m := make(map[string]string, len(rows))
+		for _, row := range rows {
+			m[row["name"]] = row["enabled"] + ":" + row["disabled_reason"]
+		}
+		return m
+	}
+
+	r := newRegistry()
+	require.Equal(t, []string{"fleetd_table_stats", "all_platforms", "linux_only"}, pluginNames(r.Plugins()))
+	require.Equal(t, map[string]string{
+		"fleetd_table_stats": "1:",
+		"all_platforms":      "1:",
+		"linux_only":         "1:",
+		"admin_only":         "0:requires administrator privileges",
+		"off_by_default":     "0:disabled by default",
+	}, statuses(r))
+
+	isAdmin = true
+	r = newRegistry()
+	r.SetEnabled("off_by_default", true)
+	r.SetEnabled("linux_only", false)
+	r.SetEnabled("darwin_only", true)
+	require.Equal(t, []string{"fleetd_table_stats", "all_platforms", "admin_only", "off_by_default"}, pluginNames(r.Plugins()))
+	require.Equal(t, map[string]string{
+		"fleetd_table_stats": "1:",
+		"all_platforms":      "1:",
+		"linux_only":         "0:disabled by configuration",
+		"admin_only":         "1:",
+		"off_by_default":     "1:",
+	}, statuses(r))
+}
+
+func TestRegistryStats(t *testing.T) {
+	ctx := context.Background()
+	fail := false
+	generate := func(context.Context, table.QueryContext) ([]map[string]string, error) {
+		if fail {
+			return nil, errors.New("fail")
+		}
+		return []map[string]string{{"a": "1"}, {"a": "2"}}, nil
+	}
+
+	r := NewRegistry()
+	r.Register(Table("slow_table", []table.ColumnDefinition{table.TextColumn("a")}, generate))
+	plugins := r.Plugins()
+	require.Len(t, plugins, 2)
+	plugin := plugins[1]
+	require.Equal(t, "slow_table", plugin.Name())
+
+	// only generate calls are recorded
+	resp := plugin.Call(ctx, osquery_gen.ExtensionPluginRequest{"action": "columns"})
+	require.EqualValues(t, 0, resp.Status.Code)
+	resp = plugin.Call(ctx, osquery_gen.ExtensionPluginRequest{"action": "generate", "context": "{}"})
+	require.EqualValues(t, 0, resp.Status.Code)
+	require.Len(t, resp.Response, 2)
+	fail = true
+	resp = plugin.Call(ctx, osquery_gen.ExtensionPluginRequest{"action": "generate", "context": "{}"})
+	require.NotEqualValues(t, 0, resp.Status.Code)
+
+	rows, err := r.stats.GenerateFunc(ctx, table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "slow_table", rows[1]["name"])
+	require.Equal(t, "2", rows[1]["queries"])
+	require.Equal(t, "1", rows[1]["errors"])
+	require.Equal(t, "2", rows[1]["rows"])
+	require.NotEqual(t, "0", rows[1]["last_queried_at"])
+	require.Equal(t, "0", rows[0]["queries"])
+}
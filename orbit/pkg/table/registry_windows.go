@@ -0,0 +1,11 @@
+//go:build windows
+
+package table
+
+import "golang.org/x/sys/windows"
+
+// processHasAdminPrivileges returns true if the process token is elevated,
+// which is the case when running as the SYSTEM service.
+func processHasAdminPrivileges() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
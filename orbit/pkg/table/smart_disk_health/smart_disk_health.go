@@ -0,0 +1,418 @@
+// Package smart_disk_health implements the smart_disk_health table, that
+// reports the SMART health of the physical disks of the host (overall health,
+// wear level, reallocated sectors, media errors) so that policies can detect
+// disks that need to be replaced before they fail.
+//
+// The data is read with smartctl (smartmontools) when it is installed, which
+// supports NVMe and SATA disks on all platforms. Otherwise, the native
+// sources of each platform are used: system_profiler on macOS (overall health
+// only) and the storage reliability counters on Windows.
+package smart_disk_health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+)
+
+// Values of the health column.
+const (
+	healthPassed  = "passed"
+	healthFailed  = "failed"
+	healthUnknown = "unknown"
+)
+
+// Values of the source column.
+const (
+	sourceSmartctl                  = "smartctl"
+	sourceSystemProfiler            = "system_profiler"
+	sourceStorageReliabilityCounter = "storage_reliability_counter"
+)
+
+// commandTimeout is the maximum duration of each of the commands run to
+// generate the table.
+const commandTimeout = 30 * time.Second
+
+// disk is the SMART health of a physical disk, nil fields are unknown.
+type disk struct {
+	device             string
+	model              string
+	serial             string
+	protocol           string
+	health             string
+	wearLevelPercent   *int64
+	reallocatedSectors *int64
+	mediaErrors        *int64
+	powerOnHours       *int64
+	temperatureCelsius *int64
+	source             string
+}
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("device"),
+		table.TextColumn("model"),
+		table.TextColumn("serial"),
+		table.TextColumn("protocol"),
+		table.TextColumn("health"),
+		table.IntegerColumn("wear_level_percent"),
+		table.BigIntColumn("reallocated_sectors"),
+		table.BigIntColumn("media_errors"),
+		table.BigIntColumn("power_on_hours"),
+		table.IntegerColumn("temperature_celsius"),
+		table.TextColumn("source"),
+	}
+}
+
+// GenerateFunc is called to return the results for the table at query time.
+// There is one row for each physical disk.
+func GenerateFunc(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	disks, err := smartctlDisks(ctx)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debug().Err(err).Msg("smart_disk_health: smartctl failed, falling back to native source")
+		}
+		disks, err = nativeDisks(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]map[string]string, 0, len(disks))
+	for _, d := range disks {
+		rows = append(rows, d.row())
+	}
+	return rows, nil
+}
+
+func (d disk) row() map[string]string {
+	formatInt := func(v *int64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	}
+	health := d.health
+	if health == "" {
+		health = healthUnknown
+	}
+	return map[string]string{
+		"device":              d.device,
+		"model":               d.model,
+		"serial":              d.serial,
+		"protocol":            d.protocol,
+		"health":              health,
+		"wear_level_percent":  formatInt(d.wearLevelPercent),
+		"reallocated_sectors": formatInt(d.reallocatedSectors),
+		"media_errors":        formatInt(d.mediaErrors),
+		"power_on_hours":      formatInt(d.powerOnHours),
+		"temperature_celsius": formatInt(d.temperatureCelsius),
+		"source":              d.source,
+	}
+}
+
+// smartctlDisks returns the disks reported by smartctl. It returns an error
+// that wraps os.ErrNotExist if smartctl is not installed.
+func smartctlDisks(ctx context.Context) ([]disk, error) {
+	bin, err := findBinary(smartctlPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runSmartctl(ctx, bin, "--scan-open", "--json")
+	if err != nil {
+		return nil, err
+	}
+	devices, err := parseSmartctlScan(out)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []disk
+	for _, dev := range devices {
+		out, err := runSmartctl(ctx, bin, "--all", "--json", "--device", dev.Type, dev.Name)
+		if err != nil {
+			log.Debug().Err(err).Str("device", dev.Name).Msg("smart_disk_health: smartctl device info")
+			continue
+		}
+		d, err := parseSmartctlInfo(out)
+		if err != nil {
+			log.Debug().Err(err).Str("device", dev.Name).Msg("smart_disk_health: parse smartctl device info")
+			continue
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func findBinary(paths []string) (string, error) {
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	if p, err := exec.LookPath("smartctl"); err == nil {
+		return p, nil
+	}
+	return "", fmt.Errorf("smartctl not found: %w", os.ErrNotExist)
+}
+
+// runSmartctl runs smartctl with the provided arguments. The exit status of
+// smartctl is a bit mask, where only the two lowest bits indicate that the
+// command failed, the others report the health of the disk, so the output is
+// returned in that case.
+func runSmartctl(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode()&0b11 == 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %w", cmd.String(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+type smartctlDevice struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Protocol string `json:"protocol"`
+}
+
+func parseSmartctlScan(out []byte) ([]smartctlDevice, error) {
+	var scan struct {
+		Devices []smartctlDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("unmarshal smartctl scan: %w", err)
+	}
+	return scan.Devices, nil
+}
+
+// ATA attributes reported by the disk vendors, see
+// https://en.wikipedia.org/wiki/Self-Monitoring,_Analysis_and_Reporting_Technology#Known_ATA_S.M.A.R.T._attributes
+const (
+	ataReallocatedSectorCount = 5
+	ataTemperature            = 194
+	// the normalized value of the following attributes is the remaining life
+	// of the disk as a percentage.
+	ataWearLevelingCount     = 177
+	ataSSDLifeLeft           = 231
+	ataMediaWearoutIndicator = 233
+)
+
+type smartctlInfo struct {
+	Device struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	NVMeHealth *struct {
+		PercentageUsed int64 `json:"percentage_used"`
+		MediaErrors    int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	ATAAttributes *struct {
+		Table []struct {
+			ID    int   `json:"id"`
+			Value int64 `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+func parseSmartctlInfo(out []byte) (disk, error) {
+	var info smartctlInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return disk{}, fmt.Errorf("unmarshal smartctl info: %w", err)
+	}
+
+	d := disk{
+		device:   info.Device.Name,
+		model:    info.ModelName,
+		serial:   info.SerialNumber,
+		protocol: strings.ToLower(info.Device.Protocol),
+		health:   healthUnknown,
+		source:   sourceSmartctl,
+	}
+	if info.SmartStatus != nil {
+		d.health = healthFailed
+		if info.SmartStatus.Passed {
+			d.health = healthPassed
+		}
+	}
+	if info.PowerOnTime != nil {
+		d.powerOnHours = ptr(info.PowerOnTime.Hours)
+	}
+	if info.Temperature != nil {
+		d.temperatureCelsius = ptr(info.Temperature.Current)
+	}
+	if info.NVMeHealth != nil {
+		d.wearLevelPercent = ptr(info.NVMeHealth.PercentageUsed)
+		d.mediaErrors = ptr(info.NVMeHealth.MediaErrors)
+	}
+	if info.ATAAttributes != nil {
+		for _, attr := range info.ATAAttributes.Table {
+			switch attr.ID {
+			case ataReallocatedSectorCount:
+				d.reallocatedSectors = ptr(attr.Raw.Value)
+			case ataTemperature:
+				if d.temperatureCelsius == nil {
+					// the lowest byte of the raw value is the current temperature
+					d.temperatureCelsius = ptr(attr.Raw.Value & 0xff)
+				}
+			case ataWearLevelingCount, ataSSDLifeLeft, ataMediaWearoutIndicator:
+				if d.wearLevelPercent == nil {
+					d.wearLevelPercent = ptr(100 - attr.Value)
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// parseSystemProfiler parses the output of
+// "system_profiler -json SPNVMeDataType SPSerialATADataType".
+func parseSystemProfiler(out []byte) ([]disk, error) {
+	type item struct {
+		Name        string `json:"_name"`
+		BSDName     string `json:"bsd_name"`
+		Model       string `json:"device_model"`
+		Serial      string `json:"device_serial"`
+		SmartStatus string `json:"smart_status"`
+	}
+	type controller struct {
+		Items []item `json:"_items"`
+	}
+	var data struct {
+		NVMe []controller `json:"SPNVMeDataType"`
+		SATA []controller `json:"SPSerialATADataType"`
+	}
+	if err := json.Unmarshal(out, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal system_profiler: %w", err)
+	}
+
+	var disks []disk
+	for protocol, controllers := range map[string][]controller{"nvme": data.NVMe, "ata": data.SATA} {
+		for _, c := range controllers {
+			for _, it := range c.Items {
+				if it.SmartStatus == "" {
+					// not a disk (e.g. an optical drive)
+					continue
+				}
+				health := healthUnknown
+				switch it.SmartStatus {
+				case "Verified":
+					health = healthPassed
+				case "Failing":
+					health = healthFailed
+				}
+				model := it.Model
+				if model == "" {
+					model = it.Name
+				}
+				device := it.BSDName
+				if device != "" {
+					device = "/dev/" + device
+				}
+				disks = append(disks, disk{
+					device:   device,
+					model:    strings.TrimSpace(model),
+					serial:   strings.TrimSpace(it.Serial),
+					protocol: protocol,
+					health:   health,
+					source:   sourceSystemProfiler,
+				})
+			}
+		}
+	}
+	sortDisks(disks)
+	return disks, nil
+}
+
+// parseStorageReliability parses the JSON array of physical disks with their
+// reliability counters output by PowerShell on Windows.
+func parseStorageReliability(out []byte) ([]disk, error) {
+	var items []struct {
+		DeviceID              string `json:"DeviceId"`
+		FriendlyName          string `json:"FriendlyName"`
+		SerialNumber          string `json:"SerialNumber"`
+		BusType               string `json:"BusType"`
+		HealthStatus          string `json:"HealthStatus"`
+		Wear                  *int64 `json:"Wear"`
+		ReadErrorsUncorrected *int64 `json:"ReadErrorsUncorrected"`
+		PowerOnHours          *int64 `json:"PowerOnHours"`
+		Temperature           *int64 `json:"Temperature"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &items); err != nil {
+		return nil, fmt.Errorf("unmarshal storage reliability counters: %w", err)
+	}
+
+	disks := make([]disk, 0, len(items))
+	for _, it := range items {
+		health := healthUnknown
+		switch it.HealthStatus {
+		case "Healthy":
+			health = healthPassed
+		case "Warning", "Unhealthy":
+			// a warning is reported when the disk predicts a failure
+			health = healthFailed
+		}
+		d := disk{
+			device:             `\\.\PHYSICALDRIVE` + it.DeviceID,
+			model:              strings.TrimSpace(it.FriendlyName),
+			serial:             strings.TrimSpace(it.SerialNumber),
+			protocol:           strings.ToLower(it.BusType),
+			health:             health,
+			wearLevelPercent:   it.Wear,
+			mediaErrors:        it.ReadErrorsUncorrected,
+			powerOnHours:       it.PowerOnHours,
+			temperatureCelsius: it.Temperature,
+			source:             sourceStorageReliabilityCounter,
+		}
+		// 0 is reported when the temperature is not supported
+		if d.temperatureCelsius != nil && *d.temperatureCelsius == 0 {
+			d.temperatureCelsius = nil
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func sortDisks(disks []disk) {
+	sort.Slice(disks, func(i, j int) bool { return disks[i].device < disks[j].device })
+}
+
+func ptr(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,28 @@
+//go:build darwin
+
+package smart_disk_health
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+var smartctlPaths = []string{
+	"/opt/homebrew/bin/smartctl",
+	"/usr/local/sbin/smartctl",
+	"/usr/local/bin/smartctl",
+}
+
+// nativeDisks returns the disks reported by system_profiler, which only
+// reports the overall health of the disks.
+func nativeDisks(ctx context.Context) ([]disk, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "/usr/sbin/system_profiler", "-json", "SPNVMeDataType", "SPSerialATADataType").Output()
+	if err != nil {
+		return nil, fmt.Errorf("run system_profiler: %w", err)
+	}
+	return parseSystemProfiler(out)
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package smart_disk_health
+
+import "context"
+
+var smartctlPaths = []string{
+	"/usr/sbin/smartctl",
+	"/usr/bin/smartctl",
+	"/usr/local/sbin/smartctl",
+}
+
+// nativeDisks returns no disks, as there is no native source of the SMART
+// health of the disks on Linux, smartctl must be installed.
+func nativeDisks(context.Context) ([]disk, error) {
+	return nil, nil
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !windows && !linux
+
+package smart_disk_health
+
+import "context"
+
+var smartctlPaths []string
+
+func nativeDisks(context.Context) ([]disk, error) {
+	return nil, nil
+}
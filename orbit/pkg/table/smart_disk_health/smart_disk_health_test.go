@@ -0,0 +1,135 @@
+package smart_disk_health
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return b
+}
+
+func rows(disks []disk) []map[string]string {
+	var res []map[string]string
+	for _, d := range disks {
+		res = append(res, d.row())
+	}
+	return res
+}
+
+func TestParseSmartctl(t *testing.T) {
+	devices, err := parseSmartctlScan(readTestdata(t, "smartctl_scan.json"))
+	require.NoError(t, err)
+	require.Equal(t, []smartctlDevice{
+		{Name: "/dev/nvme0", Type: "nvme", Protocol: "NVMe"},
+		{Name: "/dev/sda", Type: "sat", Protocol: "ATA"},
+	}, devices)
+
+	nvme, err := parseSmartctlInfo(readTestdata(t, "smartctl_nvme.json"))
+	require.NoError(t, err)
+	sata, err := parseSmartctlInfo(readTestdata(t, "smartctl_sata.json"))
+	require.NoError(t, err)
+
+	require.Equal(t, []map[string]string{
+		{
+			"device":              "/dev/nvme0",
+			"model":               "Samsung SSD 980 PRO 1TB",
+			"serial":              "S5GXNF0R123456",
+			"protocol":            "nvme",
+			"health":              "passed",
+			"wear_level_percent":  "3",
+			"reallocated_sectors": "",
+			"media_errors":        "0",
+			"power_on_hours":      "5210",
+			"temperature_celsius": "41",
+			"source":              "smartctl",
+		},
+		{
+			"device":              "/dev/sda",
+			"model":               "CT500MX500SSD1",
+			"serial":              "1904E1E2A3B4",
+			"protocol":            "ata",
+			"health":              "failed",
+			"wear_level_percent":  "12",
+			"reallocated_sectors": "24",
+			"media_errors":        "",
+			"power_on_hours":      "18034",
+			"temperature_celsius": "36",
+			"source":              "smartctl",
+		},
+	}, rows([]disk{nvme, sata}))
+
+	_, err = parseSmartctlInfo([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestParseSystemProfiler(t *testing.T) {
+	disks, err := parseSystemProfiler(readTestdata(t, "system_profiler.json"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"device":              "/dev/disk0",
+			"model":               "APPLE SSD AP0512Q",
+			"serial":              "0ba0212345678901",
+			"protocol":            "nvme",
+			"health":              "passed",
+			"wear_level_percent":  "",
+			"reallocated_sectors": "",
+			"media_errors":        "",
+			"power_on_hours":      "",
+			"temperature_celsius": "",
+			"source":              "system_profiler",
+		},
+		{
+			"device":              "/dev/disk1",
+			"model":               "WDC WD10EZEX-08WN4A0",
+			"serial":              "WD-WCC6Y1234567",
+			"protocol":            "ata",
+			"health":              "failed",
+			"wear_level_percent":  "",
+			"reallocated_sectors": "",
+			"media_errors":        "",
+			"power_on_hours":      "",
+			"temperature_celsius": "",
+			"source":              "system_profiler",
+		},
+	}, rows(disks))
+}
+
+func TestParseStorageReliability(t *testing.T) {
+	disks, err := parseStorageReliability(readTestdata(t, "storage_reliability.json"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"device":              `\\.\PHYSICALDRIVE0`,
+			"model":               "NVMe KXG60ZNV512G TOSHIBA",
+			"serial":              "0000_0000_0000_0010_8CE3_8E00_0123_4567.",
+			"protocol":            "nvme",
+			"health":              "passed",
+			"wear_level_percent":  "2",
+			"reallocated_sectors": "",
+			"media_errors":        "0",
+			"power_on_hours":      "3120",
+			"temperature_celsius": "38",
+			"source":              "storage_reliability_counter",
+		},
+		{
+			"device":              `\\.\PHYSICALDRIVE1`,
+			"model":               "ST2000DM008-2FR102",
+			"serial":              "ZFL1ABCD",
+			"protocol":            "sata",
+			"health":              "failed",
+			"wear_level_percent":  "",
+			"reallocated_sectors": "",
+			"media_errors":        "",
+			"power_on_hours":      "",
+			"temperature_celsius": "",
+			"source":              "storage_reliability_counter",
+		},
+	}, rows(disks))
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package smart_disk_health
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+var smartctlPaths = []string{
+	`C:\Program Files\smartmontools\bin\smartctl.exe`,
+}
+
+// storageReliabilityScript lists the physical disks with their reliability
+// counters as a JSON array.
+const storageReliabilityScript = `ConvertTo-Json -InputObject @(Get-PhysicalDisk | ForEach-Object {
+	$c = $_ | Get-StorageReliabilityCounter
+	[PSCustomObject]@{
+		DeviceId = [string]$_.DeviceId
+		FriendlyName = $_.FriendlyName
+		SerialNumber = $_.SerialNumber
+		BusType = [string]$_.BusType
+		HealthStatus = [string]$_.HealthStatus
+		Wear = $c.Wear
+		ReadErrorsUncorrected = $c.ReadErrorsUncorrected
+		PowerOnHours = $c.PowerOnHours
+		Temperature = $c.Temperature
+	}
+})`
+
+// nativeDisks returns the disks reported by the storage reliability counters.
+func nativeDisks(ctx context.Context) ([]disk, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", storageReliabilityScript).Output()
+	if err != nil {
+		return nil, fmt.Errorf("run Get-StorageReliabilityCounter: %w", err)
+	}
+	return parseStorageReliability(out)
+}
@@ -0,0 +1,359 @@
+// Package windows_services_acl implements the windows_services_acl table,
+// that reports the access control entries of the DACL of each Windows
+// service along with whether the path of its binary is unquoted, so that
+// policies can detect services that allow a privilege escalation (services
+// that non-administrators can reconfigure, and unquoted service paths).
+//
+// The DACLs are read in their SDDL form, whose parsing is kept platform
+// independent so that it can be tested on all platforms.
+package windows_services_acl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// Columns is the schema of the table.
+func Columns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("display_name"),
+		table.TextColumn("path"),
+		table.TextColumn("start_type"),
+		table.TextColumn("user_account"),
+		table.IntegerColumn("unquoted_path"),
+		table.TextColumn("ace_type"),
+		table.TextColumn("principal"),
+		table.TextColumn("principal_sid"),
+		table.TextColumn("access"),
+		table.BigIntColumn("access_mask"),
+		table.IntegerColumn("weak_permission"),
+		table.TextColumn("sddl"),
+	}
+}
+
+// service is the configuration of a Windows service.
+type service struct {
+	name        string
+	displayName string
+	path        string
+	startType   string
+	userAccount string
+	sddl        string
+}
+
+// rows returns the rows of the service, one for each entry of its DACL.
+func (s service) rows() ([]map[string]string, error) {
+	aces, err := parseSDDLDACL(s.sddl)
+	if err != nil {
+		return nil, fmt.Errorf("parse DACL of service %s: %w", s.name, err)
+	}
+
+	unquoted := "0"
+	if isUnquotedPath(s.path) {
+		unquoted = "1"
+	}
+	rows := make([]map[string]string, 0, len(aces))
+	for _, a := range aces {
+		weak := "0"
+		if a.isWeak() {
+			weak = "1"
+		}
+		rows = append(rows, map[string]string{
+			"name":            s.name,
+			"display_name":    s.displayName,
+			"path":            s.path,
+			"start_type":      s.startType,
+			"user_account":    s.userAccount,
+			"unquoted_path":   unquoted,
+			"ace_type":        a.typ,
+			"principal":       a.principal,
+			"principal_sid":   a.principalSID(),
+			"access":          strings.Join(accessRightNames(a.mask), ","),
+			"access_mask":     strconv.FormatUint(uint64(a.mask), 10),
+			"weak_permission": weak,
+			"sddl":            s.sddl,
+		})
+	}
+	return rows, nil
+}
+
+// startTypeName returns the name of the start type of a service, see
+// https://learn.microsoft.com/en-us/windows/win32/api/winsvc/nf-winsvc-changeserviceconfigw
+func startTypeName(startType uint32) string {
+	switch startType {
+	case 0:
+		return "boot"
+	case 1:
+		return "system"
+	case 2:
+		return "auto"
+	case 3:
+		return "demand"
+	case 4:
+		return "disabled"
+	default:
+		return strconv.FormatUint(uint64(startType), 10)
+	}
+}
+
+// isUnquotedPath returns true if the path of the binary of the service
+// contains spaces and is not quoted, in which case Windows tries to run each
+// prefix of the path that ends with a space (e.g. C:\Program.exe for
+// C:\Program Files\App\app.exe) before the actual binary.
+func isUnquotedPath(path string) bool {
+	path = strings.TrimSpace(path)
+	if path == "" || strings.HasPrefix(path, `"`) {
+		return false
+	}
+	// the arguments follow the executable, which usually ends with ".exe".
+	exe := path
+	if i := strings.Index(strings.ToLower(path), ".exe"); i >= 0 {
+		exe = path[:i+len(".exe")]
+	}
+	return strings.Contains(exe, " ")
+}
+
+// Values of the ace_type column.
+const (
+	aceAllow    = "allow"
+	aceDeny     = "deny"
+	aceNullDACL = "null_dacl"
+)
+
+// ace is an access control entry.
+type ace struct {
+	typ       string
+	principal string
+	mask      uint32
+}
+
+// Access rights of the services, see
+// https://learn.microsoft.com/en-us/windows/win32/services/service-security-and-access-rights
+const (
+	serviceQueryConfig         = 0x0001
+	serviceChangeConfig        = 0x0002
+	serviceQueryStatus         = 0x0004
+	serviceEnumerateDependents = 0x0008
+	serviceStart               = 0x0010
+	serviceStop                = 0x0020
+	servicePauseContinue       = 0x0040
+	serviceInterrogate         = 0x0080
+	serviceUserDefinedControl  = 0x0100
+	accessDelete               = 0x00010000
+	accessReadControl          = 0x00020000
+	accessWriteDAC             = 0x00040000
+	accessWriteOwner           = 0x00080000
+	genericAll                 = 0x10000000
+	genericExecute             = 0x20000000
+	genericWrite               = 0x40000000
+	genericRead                = 0x80000000
+
+	serviceAllAccess = 0x000F01FF
+)
+
+var accessRights = []struct {
+	mask uint32
+	name string
+}{
+	{serviceQueryConfig, "SERVICE_QUERY_CONFIG"},
+	{serviceChangeConfig, "SERVICE_CHANGE_CONFIG"},
+	{serviceQueryStatus, "SERVICE_QUERY_STATUS"},
+	{serviceEnumerateDependents, "SERVICE_ENUMERATE_DEPENDENTS"},
+	{serviceStart, "SERVICE_START"},
+	{serviceStop, "SERVICE_STOP"},
+	{servicePauseContinue, "SERVICE_PAUSE_CONTINUE"},
+	{serviceInterrogate, "SERVICE_INTERROGATE"},
+	{serviceUserDefinedControl, "SERVICE_USER_DEFINED_CONTROL"},
+	{accessDelete, "DELETE"},
+	{accessReadControl, "READ_CONTROL"},
+	{accessWriteDAC, "WRITE_DAC"},
+	{accessWriteOwner, "WRITE_OWNER"},
+	{genericAll, "GENERIC_ALL"},
+	{genericExecute, "GENERIC_EXECUTE"},
+	{genericWrite, "GENERIC_WRITE"},
+	{genericRead, "GENERIC_READ"},
+}
+
+// sddlRights maps the SDDL codes of the access rights to their mask, for the
+// service object type.
+var sddlRights = map[string]uint32{
+	"CC": serviceQueryConfig,
+	"DC": serviceChangeConfig,
+	"LC": serviceQueryStatus,
+	"SW": serviceEnumerateDependents,
+	"RP": serviceStart,
+	"WP": serviceStop,
+	"DT": servicePauseContinue,
+	"LO": serviceInterrogate,
+	"CR": serviceUserDefinedControl,
+	"SD": accessDelete,
+	"RC": accessReadControl,
+	"WD": accessWriteDAC,
+	"WO": accessWriteOwner,
+	"GA": genericAll,
+	"GX": genericExecute,
+	"GW": genericWrite,
+	"GR": genericRead,
+	"FA": serviceAllAccess,
+	"KA": serviceAllAccess,
+}
+
+// dangerousRights are the rights that allow reconfiguring the service (and
+// thus running an arbitrary binary as its account).
+const dangerousRights = serviceChangeConfig | accessWriteDAC | accessWriteOwner | genericAll | genericWrite
+
+// sddlSIDAliases maps the SDDL aliases of the well-known principals to their
+// SID, see https://learn.microsoft.com/en-us/windows/win32/secauthz/sid-strings
+var sddlSIDAliases = map[string]string{
+	"AN": "S-1-5-7",      // anonymous logon
+	"AO": "S-1-5-32-548", // account operators
+	"AU": "S-1-5-11",     // authenticated users
+	"BA": "S-1-5-32-544", // built-in administrators
+	"BG": "S-1-5-32-546", // built-in guests
+	"BO": "S-1-5-32-551", // backup operators
+	"BU": "S-1-5-32-545", // built-in users
+	"CO": "S-1-3-0",      // creator owner
+	"IU": "S-1-5-4",      // interactive
+	"LS": "S-1-5-19",     // local service
+	"NS": "S-1-5-20",     // network service
+	"NU": "S-1-5-2",      // network
+	"PU": "S-1-5-32-547", // power users
+	"SO": "S-1-5-32-549", // server operators
+	"SU": "S-1-5-6",      // service
+	"SY": "S-1-5-18",     // local system
+	"WD": "S-1-1-0",      // everyone
+}
+
+// lowPrivilegedSIDs are the principals that include non-administrator users.
+var lowPrivilegedSIDs = map[string]bool{
+	"S-1-1-0":      true, // everyone
+	"S-1-5-2":      true, // network
+	"S-1-5-4":      true, // interactive
+	"S-1-5-7":      true, // anonymous logon
+	"S-1-5-11":     true, // authenticated users
+	"S-1-5-32-545": true, // built-in users
+	"S-1-5-32-546": true, // built-in guests
+	"S-1-5-32-547": true, // power users
+}
+
+func (a ace) principalSID() string {
+	if sid, ok := sddlSIDAliases[a.principal]; ok {
+		return sid
+	}
+	return a.principal
+}
+
+// isWeak returns true if the entry allows non-administrator users to
+// reconfigure the service.
+func (a ace) isWeak() bool {
+	switch a.typ {
+	case aceNullDACL:
+		return true
+	case aceAllow:
+		return a.mask&dangerousRights != 0 && lowPrivilegedSIDs[a.principalSID()]
+	default:
+		return false
+	}
+}
+
+// accessRightNames returns the names of the rights of the mask.
+func accessRightNames(mask uint32) []string {
+	var names []string
+	for _, r := range accessRights {
+		if mask&r.mask != 0 {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+// parseSDDLDACL returns the entries of the DACL of the security descriptor
+// in SDDL form, e.g. "O:SYG:SYD:(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;RPWP;;;IU)".
+func parseSDDLDACL(sddl string) ([]ace, error) {
+	i := strings.Index(sddl, "D:")
+	if i < 0 {
+		// no DACL information
+		return nil, nil
+	}
+	dacl := sddl[i+len("D:"):]
+	// the SACL may follow the DACL, outside of any ACE.
+	depth := 0
+	for j := 0; j < len(dacl); j++ {
+		switch dacl[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(dacl[j:], "S:") {
+			dacl = dacl[:j]
+			break
+		}
+	}
+	if strings.HasPrefix(dacl, "NO_ACCESS_CONTROL") {
+		return []ace{{typ: aceNullDACL, principal: "WD", mask: serviceAllAccess}}, nil
+	}
+
+	var aces []ace
+	for {
+		start := strings.Index(dacl, "(")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(dacl[start:], ")")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated ACE in %q", sddl)
+		}
+		a, err := parseSDDLACE(dacl[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		aces = append(aces, a)
+		dacl = dacl[start+end+1:]
+	}
+	return aces, nil
+}
+
+// parseSDDLACE parses an ACE string, i.e.
+// "ace_type;ace_flags;rights;object_guid;inherit_object_guid;account_sid".
+func parseSDDLACE(s string) (ace, error) {
+	parts := strings.Split(s, ";")
+	if len(parts) < 6 {
+		return ace{}, fmt.Errorf("invalid ACE %q", s)
+	}
+
+	var typ string
+	switch parts[0] {
+	case "A":
+		typ = aceAllow
+	case "D":
+		typ = aceDeny
+	default:
+		// audit, alarm and object ACEs are kept as is.
+		typ = parts[0]
+	}
+
+	var mask uint32
+	rights := parts[2]
+	if strings.HasPrefix(strings.ToLower(rights), "0x") {
+		v, err := strconv.ParseUint(rights[2:], 16, 32)
+		if err != nil {
+			return ace{}, fmt.Errorf("invalid rights in ACE %q: %w", s, err)
+		}
+		mask = uint32(v)
+	} else {
+		for len(rights) >= 2 {
+			r, ok := sddlRights[rights[:2]]
+			if !ok {
+				return ace{}, fmt.Errorf("unknown right %q in ACE %q", rights[:2], s)
+			}
+			mask |= r
+			rights = rights[2:]
+		}
+	}
+	return ace{typ: typ, principal: parts[5], mask: mask}, nil
+}
@@ -0,0 +1,100 @@
+package windows_services_acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUnquotedPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"", false},
+		{`C:\Windows\system32\svchost.exe -k netsvcs -p`, false},
+		{`\SystemRoot\System32\drivers\afd.sys`, false},
+		{`"C:\Program Files\Vendor\agent.exe" --service`, false},
+		{`C:\Program Files\Vendor\agent.exe`, true},
+		{`C:\Program Files\Vendor\agent.EXE --config "C:\a b\c.conf"`, true},
+		{`C:\Vendor\agent.exe --name "My Service"`, false},
+		{`C:\Vendor Tools\run`, true},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, isUnquotedPath(c.path), c.path)
+	}
+}
+
+func TestParseSDDLDACL(t *testing.T) {
+	aces, err := parseSDDLDACL("O:SYG:SYD:(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;CCDCLCSWRPWPDTLOCRSDRCWDWO;;;BA)(A;;CCLCSWLOCRRC;;;IU)" +
+		"(A;;0x2;;;S-1-5-21-1004336348-1177238915-682003330-1001)(D;;WD;;;WD)S:(AU;FA;CCDCLCSWRPWPDTLOSDRCWDWO;;;WD)")
+	require.NoError(t, err)
+	require.Equal(t, []ace{
+		{typ: "allow", principal: "SY", mask: 0x201FD},
+		{typ: "allow", principal: "BA", mask: 0xF01FF},
+		{typ: "allow", principal: "IU", mask: 0x2018D},
+		{typ: "allow", principal: "S-1-5-21-1004336348-1177238915-682003330-1001", mask: 0x2},
+		{typ: "deny", principal: "WD", mask: 0x40000},
+	}, aces)
+
+	aces, err = parseSDDLDACL("D:NO_ACCESS_CONTROL")
+	require.NoError(t, err)
+	require.Equal(t, []ace{{typ: "null_dacl", principal: "WD", mask: serviceAllAccess}}, aces)
+
+	aces, err = parseSDDLDACL("O:SYG:SY")
+	require.NoError(t, err)
+	require.Empty(t, aces)
+
+	_, err = parseSDDLDACL("D:(A;;CCZZ;;;SY)")
+	require.ErrorContains(t, err, "unknown right")
+	_, err = parseSDDLDACL("D:(A;;CC;;;SY")
+	require.ErrorContains(t, err, "unterminated")
+	_, err = parseSDDLDACL("D:(A;;CC)")
+	require.ErrorContains(t, err, "invalid ACE")
+}
+
+func TestServiceRows(t *testing.T) {
+	s := service{
+		name:        "VendorAgent",
+		displayName: "Vendor Agent",
+		path:        `C:\Program Files\Vendor\agent.exe`,
+		startType:   startTypeName(2),
+		userAccount: "LocalSystem",
+		sddl:        "D:(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;RPWPDC;;;AU)(A;;GA;;;BA)(A;;WD;;;S-1-5-32-545)",
+	}
+	rows, err := s.rows()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	for _, row := range rows {
+		for _, col := range Columns() {
+			require.Contains(t, row, col.Name)
+		}
+		require.Equal(t, "VendorAgent", row["name"])
+		require.Equal(t, "auto", row["start_type"])
+		require.Equal(t, "1", row["unquoted_path"])
+	}
+
+	require.Equal(t, "S-1-5-18", rows[0]["principal_sid"])
+	require.Equal(t, "0", rows[0]["weak_permission"])
+	require.Equal(t, "SERVICE_QUERY_CONFIG,SERVICE_QUERY_STATUS,SERVICE_ENUMERATE_DEPENDENTS,SERVICE_START,SERVICE_STOP,"+
+		"SERVICE_PAUSE_CONTINUE,SERVICE_INTERROGATE,SERVICE_USER_DEFINED_CONTROL,READ_CONTROL", rows[0]["access"])
+
+	// authenticated users can change the config
+	require.Equal(t, "S-1-5-11", rows[1]["principal_sid"])
+	require.Equal(t, "SERVICE_CHANGE_CONFIG,SERVICE_START,SERVICE_STOP", rows[1]["access"])
+	require.Equal(t, "50", rows[1]["access_mask"])
+	require.Equal(t, "1", rows[1]["weak_permission"])
+
+	// administrators are expected to have full control
+	require.Equal(t, "GENERIC_ALL", rows[2]["access"])
+	require.Equal(t, "0", rows[2]["weak_permission"])
+
+	// users can change the DACL
+	require.Equal(t, "S-1-5-32-545", rows[3]["principal_sid"])
+	require.Equal(t, "1", rows[3]["weak_permission"])
+
+	s.sddl = "D:(A;;CC;;;SY"
+	_, err = s.rows()
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+//go:build windows
+// +build windows
+
+package windows_services_acl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Generate is called to return the results for the table at query time.
+// There is one row for each entry of the DACL of each service.
+func Generate(ctx context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	// only request the access rights needed to list the services and read
+	// their configuration and DACL.
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+	if err != nil {
+		return nil, fmt.Errorf("open service control manager: %w", err)
+	}
+	m := &mgr.Mgr{Handle: scm}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+
+	var rows []map[string]string
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s, err := readService(scm, name)
+		if err != nil {
+			// the service may have been deleted since it was listed.
+			log.Debug().Err(err).Str("service", name).Msg("windows_services_acl: read service")
+			continue
+		}
+		serviceRows, err := s.rows()
+		if err != nil {
+			log.Debug().Err(err).Msg("windows_services_acl: service rows")
+			continue
+		}
+		rows = append(rows, serviceRows...)
+	}
+	return rows, nil
+}
+
+func readService(scm windows.Handle, name string) (service, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return service{}, err
+	}
+	h, err := windows.OpenService(scm, namePtr, windows.SERVICE_QUERY_CONFIG|windows.READ_CONTROL)
+	if err != nil {
+		return service{}, fmt.Errorf("open service: %w", err)
+	}
+	svc := &mgr.Service{Name: name, Handle: h}
+	defer svc.Close()
+
+	cfg, err := svc.Config()
+	if err != nil {
+		return service{}, fmt.Errorf("get service config: %w", err)
+	}
+	sd, err := windows.GetSecurityInfo(h, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return service{}, fmt.Errorf("get service security info: %w", err)
+	}
+
+	return service{
+		name:        name,
+		displayName: cfg.DisplayName,
+		path:        cfg.BinaryPathName,
+		startType:   startTypeName(cfg.StartType),
+		userAccount: cfg.ServiceStartName,
+		sddl:        sd.String(),
+	}, nil
+}
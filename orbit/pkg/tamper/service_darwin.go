@@ -0,0 +1,29 @@
+//go:build darwin
+// +build darwin
+
+package tamper
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+const serviceName = "com.fleetdm.orbit"
+
+func configFiles() []string {
+	return []string{"/Library/LaunchDaemons/com.fleetdm.orbit.plist"}
+}
+
+// disabledServiceRegexp matches the line of "launchctl print-disabled system"
+// for the orbit launch daemon when it is disabled (recent macOS versions print
+// "disabled", older versions print "true").
+var disabledServiceRegexp = regexp.MustCompile(`(?m)^\s*"com\.fleetdm\.orbit"\s*=>\s*(disabled|true)\s*$`)
+
+func isServiceDisabled() (bool, error) {
+	out, err := exec.Command("launchctl", "print-disabled", "system").Output()
+	if err != nil {
+		return false, fmt.Errorf("launchctl print-disabled: %w", err)
+	}
+	return disabledServiceRegexp.Match(out), nil
+}
@@ -0,0 +1,37 @@
+//go:build linux
+// +build linux
+
+package tamper
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const serviceName = "orbit.service"
+
+func configFiles() []string {
+	return []string{"/etc/default/orbit", "/usr/lib/systemd/system/orbit.service"}
+}
+
+func isServiceDisabled() (bool, error) {
+	// is-enabled exits with a non-zero status if the unit is not enabled, the
+	// state is still printed.
+	out, err := exec.Command("systemctl", "is-enabled", serviceName).Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return false, fmt.Errorf("systemctl is-enabled: %w", err)
+	}
+	return isDisabledUnitState(string(out)), nil
+}
+
+func isDisabledUnitState(state string) bool {
+	switch strings.TrimSpace(state) {
+	case "disabled", "masked", "masked-runtime":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,40 @@
+//go:build windows
+// +build windows
+
+package tamper
+
+import (
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceName = constant.SystemServiceName
+
+// configFiles returns no file on Windows, the service configuration is stored
+// in the registry and a disabled service is detected by isServiceDisabled.
+func configFiles() []string {
+	return nil
+}
+
+func isServiceDisabled() (bool, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return false, fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return false, fmt.Errorf("open service: %w", err)
+	}
+	defer s.Close()
+
+	cfg, err := s.Config()
+	if err != nil {
+		return false, fmt.Errorf("get service config: %w", err)
+	}
+	return cfg.StartType == windows.SERVICE_DISABLED, nil
+}
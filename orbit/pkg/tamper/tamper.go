@@ -0,0 +1,297 @@
+// Package tamper implements the detection of attempts to tamper with fleetd on
+// the host: osqueryd being killed repeatedly, the fleetd service being disabled
+// and fleetd's configuration files being modified. Detected events are
+// reported to the Fleet server.
+package tamper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// stateFileName is the name of the file, in orbit's root directory, where
+	// the state of the detector is persisted across orbit restarts.
+	stateFileName = "tamper_state.json"
+
+	// OsquerydExitsThreshold is the number of unexpected osqueryd exits within
+	// OsquerydExitsWindow that is reported as a tamper event.
+	OsquerydExitsThreshold = 3
+	// OsquerydExitsWindow is the period in which the osqueryd exits are
+	// counted.
+	OsquerydExitsWindow = 10 * time.Minute
+
+	defaultInterval = 5 * time.Minute
+)
+
+// Client defines the methods required for the API requests to the server. The
+// fleet.OrbitClient type satisfies this interface.
+type Client interface {
+	ReportTamperEvent(event *fleet.HostTamperEventPayload) error
+}
+
+// state is the state of the detector persisted on disk.
+type state struct {
+	// OsquerydExits are the times of the unexpected osqueryd exits that have
+	// not been reported yet.
+	OsquerydExits []time.Time `json:"osqueryd_exits"`
+	// ConfigFileHashes are the SHA-256 hashes of the watched configuration
+	// files, an empty string means that the file does not exist.
+	ConfigFileHashes map[string]string `json:"config_file_hashes"`
+}
+
+// stateMu protects the state file, which is written by the detector and by
+// RecordOsquerydExit.
+var stateMu sync.Mutex
+
+func readState(path string) (*state, error) {
+	var st state
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// no state yet
+	case err != nil:
+		return nil, fmt.Errorf("read tamper state: %w", err)
+	default:
+		if err := json.Unmarshal(b, &st); err != nil {
+			// a corrupted state is discarded, it would be rebuilt on the next
+			// check.
+			log.Info().Err(err).Msg("discarding invalid tamper state")
+			st = state{}
+		}
+	}
+	if st.ConfigFileHashes == nil {
+		st.ConfigFileHashes = make(map[string]string)
+	}
+	return &st, nil
+}
+
+func writeState(path string, st *state) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("marshal tamper state: %w", err)
+	}
+	if err := os.WriteFile(path, b, constant.DefaultFileMode); err != nil {
+		return fmt.Errorf("write tamper state: %w", err)
+	}
+	return nil
+}
+
+// RecordOsquerydExit records that osqueryd exited unexpectedly at the
+// provided time. It must be called by orbit when osqueryd exits without being
+// interrupted by orbit, the exits are reported by the Detector if they happen
+// repeatedly.
+func RecordOsquerydExit(rootDir string, at time.Time) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	path := filepath.Join(rootDir, stateFileName)
+	st, err := readState(path)
+	if err != nil {
+		return err
+	}
+	st.OsquerydExits = append(pruneExits(st.OsquerydExits, at), at)
+	return writeState(path, st)
+}
+
+// pruneExits returns the exits that happened within OsquerydExitsWindow of
+// now.
+func pruneExits(exits []time.Time, now time.Time) []time.Time {
+	recent := exits[:0]
+	for _, t := range exits {
+		if now.Sub(t) <= OsquerydExitsWindow {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// Detector periodically checks for tamper events and reports them to the
+// server. It is designed with Execute and Interrupt functions to be compatible
+// with oklog/run.
+type Detector struct {
+	client    Client
+	statePath string
+	interval  time.Duration
+
+	// configFiles are the paths of the watched configuration files.
+	configFiles []string
+	// serviceDisabledFn returns true if the fleetd service is disabled.
+	serviceDisabledFn func() (bool, error)
+	nowFn             func() time.Time
+
+	// serviceDisabledReported is true if the service was reported as
+	// disabled, so that it is only reported once until it is enabled again.
+	serviceDisabledReported bool
+
+	interruptCh chan struct{}
+	interruptMu sync.Once
+}
+
+// NewDetector returns a Detector that persists its state in rootDir and
+// reports the events with client.
+func NewDetector(rootDir string, client Client) *Detector {
+	return &Detector{
+		client:            client,
+		statePath:         filepath.Join(rootDir, stateFileName),
+		interval:          defaultInterval,
+		configFiles:       configFiles(),
+		serviceDisabledFn: isServiceDisabled,
+		nowFn:             time.Now,
+		interruptCh:       make(chan struct{}),
+	}
+}
+
+// Execute runs the checks immediately and then every interval, until
+// Interrupt is called.
+func (d *Detector) Execute() error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.check(); err != nil {
+			log.Error().Err(err).Msg("tamper detection")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.interruptCh:
+			return nil
+		}
+	}
+}
+
+// Interrupt stops the detector.
+func (d *Detector) Interrupt(err error) {
+	log.Debug().Err(err).Msg("interrupt tamper detector")
+	d.interruptMu.Do(func() { close(d.interruptCh) })
+}
+
+func (d *Detector) check() error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	st, err := readState(d.statePath)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{
+		d.checkOsquerydExits(st),
+		d.checkService(),
+		d.checkConfigFiles(st),
+	}
+	if err := writeState(d.statePath, st); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Detector) report(typ fleet.HostTamperEventType, details string) error {
+	log.Info().Str("type", string(typ)).Str("details", details).Msg("reporting tamper event")
+	if err := d.client.ReportTamperEvent(&fleet.HostTamperEventPayload{
+		Type:       typ,
+		Details:    details,
+		DetectedAt: d.nowFn().UTC(),
+	}); err != nil {
+		return fmt.Errorf("report %s tamper event: %w", typ, err)
+	}
+	return nil
+}
+
+func (d *Detector) checkOsquerydExits(st *state) error {
+	st.OsquerydExits = pruneExits(st.OsquerydExits, d.nowFn())
+	if len(st.OsquerydExits) < OsquerydExitsThreshold {
+		return nil
+	}
+
+	details := fmt.Sprintf("osqueryd exited unexpectedly %d times in the last %s", len(st.OsquerydExits), OsquerydExitsWindow)
+	if err := d.report(fleet.HostTamperEventOsquerydKilled, details); err != nil {
+		// keep the exits so that the event is reported on the next check
+		return err
+	}
+	st.OsquerydExits = nil
+	return nil
+}
+
+func (d *Detector) checkService() error {
+	disabled, err := d.serviceDisabledFn()
+	if err != nil {
+		return fmt.Errorf("check service status: %w", err)
+	}
+	if !disabled {
+		d.serviceDisabledReported = false
+		return nil
+	}
+	if d.serviceDisabledReported {
+		return nil
+	}
+
+	if err := d.report(fleet.HostTamperEventServiceDisabled, fmt.Sprintf("the %s service is disabled", serviceName)); err != nil {
+		return err
+	}
+	d.serviceDisabledReported = true
+	return nil
+}
+
+func (d *Detector) checkConfigFiles(st *state) error {
+	var errs []error
+	for _, path := range d.configFiles {
+		hash, err := hashFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		prevHash, ok := st.ConfigFileHashes[path]
+		if !ok || prevHash == hash {
+			// first time the file is checked, or not modified
+			st.ConfigFileHashes[path] = hash
+			continue
+		}
+
+		details := fmt.Sprintf("%s was modified", path)
+		if hash == "" {
+			details = fmt.Sprintf("%s was removed", path)
+		}
+		if err := d.report(fleet.HostTamperEventConfigModified, details); err != nil {
+			// keep the previous hash so that the event is reported on the next
+			// check
+			errs = append(errs, err)
+			continue
+		}
+		st.ConfigFileHashes[path] = hash
+	}
+	return errors.Join(errs...)
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of the file, or an empty
+// string if the file does not exist.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash config file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,167 @@
+package tamper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	events []*fleet.HostTamperEventPayload
+	err    error
+}
+
+func (m *mockClient) ReportTamperEvent(event *fleet.HostTamperEventPayload) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func newTestDetector(t *testing.T, client Client) (*Detector, string) {
+	rootDir := t.TempDir()
+	d := NewDetector(rootDir, client)
+	d.configFiles = nil
+	d.serviceDisabledFn = func() (bool, error) { return false, nil }
+	return d, rootDir
+}
+
+func TestDetectorOsquerydExits(t *testing.T) {
+	client := &mockClient{}
+	d, rootDir := newTestDetector(t, client)
+	now := time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC)
+	d.nowFn = func() time.Time { return now }
+
+	// an old exit and two recent ones, not enough to report
+	require.NoError(t, RecordOsquerydExit(rootDir, now.Add(-time.Hour)))
+	require.NoError(t, RecordOsquerydExit(rootDir, now.Add(-5*time.Minute)))
+	require.NoError(t, RecordOsquerydExit(rootDir, now.Add(-time.Minute)))
+	require.NoError(t, d.check())
+	require.Empty(t, client.events)
+
+	// a third recent exit is reported
+	require.NoError(t, RecordOsquerydExit(rootDir, now))
+	client.err = errors.New("network error")
+	require.ErrorContains(t, d.check(), "network error")
+	require.Empty(t, client.events)
+
+	// reported on the next check once the server is reachable
+	client.err = nil
+	require.NoError(t, d.check())
+	require.Len(t, client.events, 1)
+	require.Equal(t, fleet.HostTamperEventOsquerydKilled, client.events[0].Type)
+	require.Contains(t, client.events[0].Details, "3 times")
+	require.Equal(t, now, client.events[0].DetectedAt)
+
+	// not reported again
+	require.NoError(t, d.check())
+	require.Len(t, client.events, 1)
+}
+
+func TestDetectorServiceDisabled(t *testing.T) {
+	client := &mockClient{}
+	d, _ := newTestDetector(t, client)
+
+	var disabled bool
+	var disabledErr error
+	d.serviceDisabledFn = func() (bool, error) { return disabled, disabledErr }
+
+	require.NoError(t, d.check())
+	require.Empty(t, client.events)
+
+	disabledErr = errors.New("launchctl failed")
+	require.ErrorContains(t, d.check(), "launchctl failed")
+	require.Empty(t, client.events)
+	disabledErr = nil
+
+	// reported once while disabled
+	disabled = true
+	require.NoError(t, d.check())
+	require.NoError(t, d.check())
+	require.Len(t, client.events, 1)
+	require.Equal(t, fleet.HostTamperEventServiceDisabled, client.events[0].Type)
+
+	// reported again if re-enabled and then disabled
+	disabled = false
+	require.NoError(t, d.check())
+	disabled = true
+	require.NoError(t, d.check())
+	require.Len(t, client.events, 2)
+}
+
+func TestDetectorConfigFiles(t *testing.T) {
+	client := &mockClient{}
+	d, rootDir := newTestDetector(t, client)
+
+	cfgDir := t.TempDir()
+	file1, file2 := filepath.Join(cfgDir, "file1"), filepath.Join(cfgDir, "file2")
+	require.NoError(t, os.WriteFile(file1, []byte("a"), 0o600))
+	d.configFiles = []string{file1, file2}
+
+	// first check records the baseline
+	require.NoError(t, d.check())
+	require.Empty(t, client.events)
+
+	// modified file
+	require.NoError(t, os.WriteFile(file1, []byte("b"), 0o600))
+	require.NoError(t, d.check())
+	require.Len(t, client.events, 1)
+	require.Equal(t, fleet.HostTamperEventConfigModified, client.events[0].Type)
+	require.Equal(t, file1+" was modified", client.events[0].Details)
+
+	// the baseline is persisted, a new detector (e.g. after orbit restarts)
+	// detects changes made while it was not running.
+	require.NoError(t, os.Remove(file1))
+	d2 := NewDetector(rootDir, client)
+	d2.configFiles = d.configFiles
+	d2.serviceDisabledFn = d.serviceDisabledFn
+	require.NoError(t, d2.check())
+	require.Len(t, client.events, 2)
+	require.Equal(t, file1+" was removed", client.events[1].Details)
+
+	// created file
+	require.NoError(t, os.WriteFile(file2, []byte("c"), 0o600))
+	client.err = errors.New("network error")
+	require.Error(t, d2.check())
+	client.err = nil
+	require.NoError(t, d2.check())
+	require.Len(t, client.events, 3)
+	require.Equal(t, file2+" was modified", client.events[2].Details)
+
+	require.NoError(t, d2.check())
+	require.Len(t, client.events, 3)
+}
+
+func TestDetectorInvalidState(t *testing.T) {
+	client := &mockClient{}
+	d, rootDir := newTestDetector(t, client)
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, stateFileName), []byte("{"), 0o600))
+	require.NoError(t, d.check())
+	require.NoError(t, RecordOsquerydExit(rootDir, time.Now()))
+}
+
+func TestDetectorExecuteInterrupt(t *testing.T) {
+	client := &mockClient{}
+	d, _ := newTestDetector(t, client)
+	d.interval = time.Millisecond
+
+	var checks int
+	d.serviceDisabledFn = func() (bool, error) {
+		checks++
+		return false, nil
+	}
+
+	done := make(chan error)
+	go func() { done <- d.Execute() }()
+	time.Sleep(50 * time.Millisecond)
+	d.Interrupt(nil)
+	d.Interrupt(nil) // can be called more than once
+	require.NoError(t, <-done)
+	require.Greater(t, checks, 1)
+}
@@ -0,0 +1,64 @@
+package update
+
+import (
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+)
+
+// FeatureFlags holds the last feature flags received from the Fleet server.
+// It is safe for concurrent use, receivers consult it to decide whether a
+// gradually rolled out behavior is enabled on this host.
+type FeatureFlags struct {
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// Enabled returns true if the flag is enabled for this host. Unknown flags,
+// or flags not received yet from the Fleet server, are disabled.
+func (f *FeatureFlags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flags[name]
+}
+
+func (f *FeatureFlags) set(flags map[string]bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name, enabled := range flags {
+		if f.flags[name] != enabled {
+			log.Info().Msgf("feature flag %s set to %t", name, enabled)
+		}
+	}
+	f.flags = flags
+}
+
+// featureFlagsConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher and records the feature flags sent by the Fleet server.
+type featureFlagsConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// Flags records the feature flags.
+	Flags *FeatureFlags
+}
+
+func ApplyFeatureFlagsConfigFetcherMiddleware(fetcher OrbitConfigFetcher, flags *FeatureFlags) OrbitConfigFetcher {
+	return &featureFlagsConfigFetcher{
+		Fetcher: fetcher,
+		Flags:   flags,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method and records the
+// feature flags sent by the Fleet server. If the request failed, the last
+// received flags are kept.
+func (h *featureFlagsConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := h.Fetcher.GetConfig()
+	if err == nil {
+		h.Flags.set(cfg.FeatureFlags)
+	}
+	return cfg, err
+}
@@ -2,13 +2,24 @@ package update
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/orbit/pkg/bitlocker"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/fileretrieval"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/logcollection"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/luks"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/profiles"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/profiling"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/remotequery"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/scripts"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/rs/zerolog/log"
@@ -297,6 +308,10 @@ type runScriptsConfigFetcher struct {
 	// back its results.
 	ScriptsClient scripts.Client
 
+	// ScriptsResultsDir is the directory where the script results are
+	// persisted until they are saved by the Fleet server.
+	ScriptsResultsDir string
+
 	// the dynamic scripts enabled check is done to check via mdm configuration
 	// profile if the host is allowed to run dynamic scripts. It is only done
 	// on macos and only if ScriptsExecutionEnabled is false.
@@ -315,12 +330,13 @@ type runScriptsConfigFetcher struct {
 }
 
 func ApplyRunScriptsConfigFetcherMiddleware(
-	fetcher OrbitConfigFetcher, scriptsEnabled bool, scriptsClient scripts.Client,
+	fetcher OrbitConfigFetcher, scriptsEnabled bool, scriptsClient scripts.Client, scriptsResultsDir string,
 ) (OrbitConfigFetcher, func() bool) {
 	scriptsFetcher := &runScriptsConfigFetcher{
 		Fetcher:                            fetcher,
 		ScriptsExecutionEnabled:            scriptsEnabled,
 		ScriptsClient:                      scriptsClient,
+		ScriptsResultsDir:                  scriptsResultsDir,
 		dynamicScriptsEnabledCheckInterval: 5 * time.Minute,
 	}
 	// start the dynamic check for scripts enabled if required
@@ -376,6 +392,7 @@ func (h *runScriptsConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
 			runner := &scripts.Runner{
 				ScriptExecutionEnabled: h.scriptsEnabled(),
 				Client:                 h.ScriptsClient,
+				ResultsDir:             h.ScriptsResultsDir,
 			}
 			fn := runner.Run
 			if h.runScriptsFn != nil {
@@ -405,6 +422,318 @@ func (h *runScriptsConfigFetcher) scriptsEnabled() bool {
 	return h.ScriptsExecutionEnabled || h.dynamicScriptsEnabled.Load()
 }
 
+// logCollectionConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher and detects if the fleet server sent a list of log
+// collection requests. If so, it collects and uploads the logs in a
+// goroutine, ensuring only one collection runs at a time.
+type logCollectionConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// LogCollectionEnabled returns true if this agent allows log collection.
+	// As collecting logs executes commands on the host, this follows the
+	// scripts execution setting of the agent.
+	LogCollectionEnabled func() bool
+
+	// LogCollectionClient is the client to use to fetch the log collection
+	// requests and upload the collected logs.
+	LogCollectionClient logcollection.Client
+
+	// for tests, to be able to mock the collection. If nil, will use
+	// (logcollection.Collector{...}).Run.
+	runLogCollectionFn func(*logcollection.Collector, []string) error
+
+	// ensures only one log collection runs at a time
+	mu sync.Mutex
+}
+
+func ApplyLogCollectionConfigFetcherMiddleware(
+	fetcher OrbitConfigFetcher, enabledFn func() bool, client logcollection.Client,
+) OrbitConfigFetcher {
+	return &logCollectionConfigFetcher{
+		Fetcher:              fetcher,
+		LogCollectionEnabled: enabledFn,
+		LogCollectionClient:  client,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server sent a list of log collection requests, starts a goroutine to
+// process them.
+func (h *logCollectionConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := h.Fetcher.GetConfig()
+
+	if err == nil && len(cfg.Notifications.PendingLogCollectionIDs) > 0 {
+		if h.mu.TryLock() {
+			execIDs := cfg.Notifications.PendingLogCollectionIDs
+			log.Debug().Msgf("received request to collect logs %v", execIDs)
+
+			collector := &logcollection.Collector{
+				Enabled: h.LogCollectionEnabled(),
+				Client:  h.LogCollectionClient,
+			}
+			fn := collector.Run
+			if h.runLogCollectionFn != nil {
+				fn = func(execIDs []string) error {
+					return h.runLogCollectionFn(collector, execIDs)
+				}
+			}
+
+			go func() {
+				defer h.mu.Unlock()
+
+				if err := fn(execIDs); err != nil {
+					log.Info().Err(err).Msg("collecting logs failed")
+					return
+				}
+				log.Debug().Msgf("collecting logs %v succeeded", execIDs)
+			}()
+		}
+	}
+	return cfg, err
+}
+
+// fileRetrievalConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher and processes the file retrieval requests sent by the
+// Fleet server.
+type fileRetrievalConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// FileRetrievalEnabled returns true if this agent allows file retrieval.
+	// As it gives access to the host's filesystem, this follows the scripts
+	// execution setting of the agent.
+	FileRetrievalEnabled func() bool
+
+	// FileRetrievalClient is the client to use to fetch the file retrieval
+	// requests and upload the files.
+	FileRetrievalClient fileretrieval.Client
+
+	// for tests, to be able to mock the retrieval. If nil, will use
+	// (fileretrieval.Retriever{...}).Run.
+	runFileRetrievalFn func(*fileretrieval.Retriever, []string) error
+
+	// ensures only one file retrieval runs at a time
+	mu sync.Mutex
+}
+
+func ApplyFileRetrievalConfigFetcherMiddleware(
+	fetcher OrbitConfigFetcher, enabledFn func() bool, client fileretrieval.Client,
+) OrbitConfigFetcher {
+	return &fileRetrievalConfigFetcher{
+		Fetcher:              fetcher,
+		FileRetrievalEnabled: enabledFn,
+		FileRetrievalClient:  client,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server sent a list of file retrieval requests, starts a goroutine to
+// process them.
+func (h *fileRetrievalConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := h.Fetcher.GetConfig()
+
+	if err == nil && len(cfg.Notifications.PendingFileRetrievalIDs) > 0 {
+		if h.mu.TryLock() {
+			execIDs := cfg.Notifications.PendingFileRetrievalIDs
+			log.Debug().Msgf("received request to retrieve files %v", execIDs)
+
+			retriever := &fileretrieval.Retriever{
+				Enabled: h.FileRetrievalEnabled(),
+				Client:  h.FileRetrievalClient,
+			}
+			fn := retriever.Run
+			if h.runFileRetrievalFn != nil {
+				fn = func(execIDs []string) error {
+					return h.runFileRetrievalFn(retriever, execIDs)
+				}
+			}
+
+			go func() {
+				defer h.mu.Unlock()
+
+				if err := fn(execIDs); err != nil {
+					log.Info().Err(err).Msg("retrieving files failed")
+					return
+				}
+				log.Debug().Msgf("retrieving files %v succeeded", execIDs)
+			}()
+		}
+	}
+	return cfg, err
+}
+
+// pprofCaptureConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher and processes the pprof capture requests sent by the
+// Fleet server.
+type pprofCaptureConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// PprofCaptureClient is the client to use to fetch the pprof capture
+	// requests and upload the profiles.
+	PprofCaptureClient profiling.Client
+
+	// for tests, to be able to mock the capture. If nil, will use
+	// (profiling.Capturer{...}).Run.
+	runPprofCaptureFn func(*profiling.Capturer, []string) error
+
+	// ensures only one pprof capture runs at a time
+	mu sync.Mutex
+}
+
+func ApplyPprofCaptureConfigFetcherMiddleware(fetcher OrbitConfigFetcher, client profiling.Client) OrbitConfigFetcher {
+	return &pprofCaptureConfigFetcher{
+		Fetcher:            fetcher,
+		PprofCaptureClient: client,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server sent a list of pprof capture requests, starts a goroutine to process
+// them.
+func (h *pprofCaptureConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := h.Fetcher.GetConfig()
+
+	if err == nil && len(cfg.Notifications.PendingPprofCaptureIDs) > 0 {
+		if h.mu.TryLock() {
+			execIDs := cfg.Notifications.PendingPprofCaptureIDs
+			log.Debug().Msgf("received request to capture pprof profiles %v", execIDs)
+
+			capturer := &profiling.Capturer{Client: h.PprofCaptureClient}
+			fn := capturer.Run
+			if h.runPprofCaptureFn != nil {
+				fn = func(execIDs []string) error {
+					return h.runPprofCaptureFn(capturer, execIDs)
+				}
+			}
+
+			go func() {
+				defer h.mu.Unlock()
+
+				if err := fn(execIDs); err != nil {
+					log.Info().Err(err).Msg("capturing pprof profiles failed")
+					return
+				}
+				log.Debug().Msgf("capturing pprof profiles %v succeeded", execIDs)
+			}()
+		}
+	}
+	return cfg, err
+}
+
+// WindowsServiceConfigurer applies the settings of the fleetd Windows service
+// and receives the heartbeats of fleetd's main loop. The
+// servicerecovery.Manager type satisfies this interface.
+type WindowsServiceConfigurer interface {
+	Heartbeat()
+	ApplyConfig(cfg *fleet.OrbitWindowsServiceConfig) error
+}
+
+// windowsServiceConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher, sends a heartbeat to the service watchdog on each
+// config fetch and applies the Windows service settings sent by the Fleet
+// server.
+type windowsServiceConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// Configurer applies the Windows service settings.
+	Configurer WindowsServiceConfigurer
+}
+
+func ApplyWindowsServiceConfigFetcherMiddleware(fetcher OrbitConfigFetcher, configurer WindowsServiceConfigurer) OrbitConfigFetcher {
+	return &windowsServiceConfigFetcher{
+		Fetcher:    fetcher,
+		Configurer: configurer,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method and applies the
+// Windows service settings sent by the fleet server.
+func (w *windowsServiceConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := w.Fetcher.GetConfig()
+
+	// the config is fetched periodically by fleetd's main loop, even if the
+	// request failed (e.g. the server is not reachable) the loop is alive.
+	w.Configurer.Heartbeat()
+
+	if err == nil {
+		if err := w.Configurer.ApplyConfig(cfg.WindowsService); err != nil {
+			log.Info().Err(err).Msg("applying windows service config failed")
+		}
+	}
+	return cfg, err
+}
+
+// remoteQueryConfigFetcher is a kind of middleware that wraps an
+// OrbitConfigFetcher and processes the remote query requests sent by the
+// Fleet server.
+type remoteQueryConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// RemoteQueryClient is the client to use to fetch the remote query
+	// requests and send the results.
+	RemoteQueryClient remotequery.Client
+
+	// ExtensionSocket is the path of osqueryd's extension manager socket.
+	ExtensionSocket string
+
+	// for tests, to be able to mock the queries. If nil, will use
+	// (remotequery.Runner{...}).Run.
+	runRemoteQueryFn func(*remotequery.Runner, []string) error
+
+	// ensures only one batch of remote queries runs at a time
+	mu sync.Mutex
+}
+
+func ApplyRemoteQueryConfigFetcherMiddleware(fetcher OrbitConfigFetcher, client remotequery.Client, extensionSocket string) OrbitConfigFetcher {
+	return &remoteQueryConfigFetcher{
+		Fetcher:           fetcher,
+		RemoteQueryClient: client,
+		ExtensionSocket:   extensionSocket,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server sent a list of remote query requests, starts a goroutine to process
+// them.
+func (h *remoteQueryConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := h.Fetcher.GetConfig()
+
+	if err == nil && len(cfg.Notifications.PendingRemoteQueryIDs) > 0 {
+		if h.mu.TryLock() {
+			execIDs := cfg.Notifications.PendingRemoteQueryIDs
+			log.Debug().Msgf("received request to run remote queries %v", execIDs)
+
+			runner := &remotequery.Runner{Client: h.RemoteQueryClient, ExtensionSocket: h.ExtensionSocket}
+			fn := runner.Run
+			if h.runRemoteQueryFn != nil {
+				fn = func(execIDs []string) error {
+					return h.runRemoteQueryFn(runner, execIDs)
+				}
+			}
+
+			go func() {
+				defer h.mu.Unlock()
+
+				if err := fn(execIDs); err != nil {
+					log.Info().Err(err).Msg("running remote queries failed")
+					return
+				}
+				log.Debug().Msgf("running remote queries %v succeeded", execIDs)
+			}()
+		}
+	}
+	return cfg, err
+}
+
 type DiskEncryptionKeySetter interface {
 	SetOrUpdateDiskEncryptionKey(diskEncryptionStatus fleet.OrbitHostDiskEncryptionKeyPayload) error
 }
@@ -654,3 +983,251 @@ func (w *windowsMDMBitlockerConfigFetcher) updateFleetServer(key string, err err
 
 	return w.EncryptionResult.SetOrUpdateDiskEncryptionKey(payload)
 }
+
+// LinuxDiskEncryptionKeyEscrower is the interface used to escrow the LUKS
+// passphrase of a Linux host with the Fleet server.
+type LinuxDiskEncryptionKeyEscrower interface {
+	SendLinuxKeyEscrowResponse(payload fleet.OrbitHostLUKSDataPayload) error
+}
+
+// luksKeySlotFileName is the name of the file in the root directory that
+// records the LUKS key slot of the passphrase escrowed with Fleet, so that it
+// can be removed when the passphrase is rotated.
+const luksKeySlotFileName = "luks-key-slot"
+
+type linuxDiskEncryptionEscrowConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// Frequency is the minimum amount of time that must pass between two
+	// attempts to escrow the passphrase, as each attempt prompts the user.
+	Frequency time.Duration
+
+	// Escrower sends the passphrase (or the error) to the Fleet server.
+	Escrower LinuxDiskEncryptionKeyEscrower
+
+	// RootDir is the orbit root directory, where the escrowed key slot is
+	// recorded.
+	RootDir string
+
+	// tracks last time an escrow was attempted
+	lastRun time.Time
+
+	// ensures only one escrow runs at a time
+	mu sync.Mutex
+
+	// for tests, to be able to mock the LUKS operations. If nil, will use the
+	// luks package functions.
+	rootDeviceFn         func() (string, error)
+	promptPassphraseFn   func() (string, error)
+	generatePassphraseFn func() (string, error)
+	addKeyFn             func(device, passphrase, newPassphrase string) (uint, error)
+	killSlotFn           func(device string, slot uint, passphrase string) error
+}
+
+// ApplyLinuxDiskEncryptionEscrowFetcherMiddleware returns an OrbitConfigFetcher
+// that escrows a new passphrase of the LUKS encrypted root volume when the
+// Fleet server sets the RunLinuxDiskEncryptionEscrow notification.
+func ApplyLinuxDiskEncryptionEscrowFetcherMiddleware(
+	fetcher OrbitConfigFetcher,
+	frequency time.Duration,
+	escrower LinuxDiskEncryptionKeyEscrower,
+	rootDir string,
+) OrbitConfigFetcher {
+	return &linuxDiskEncryptionEscrowConfigFetcher{
+		Fetcher:              fetcher,
+		Frequency:            frequency,
+		Escrower:             escrower,
+		RootDir:              rootDir,
+		rootDeviceFn:         luks.RootDevice,
+		promptPassphraseFn:   luks.PromptPassphrase,
+		generatePassphraseFn: luks.GeneratePassphrase,
+		addKeyFn:             luks.AddKey,
+		killSlotFn:           luks.KillSlot,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server set the "RunLinuxDiskEncryptionEscrow" flag to true, adds a new
+// passphrase to the LUKS encrypted root volume and escrows it.
+func (l *linuxDiskEncryptionEscrowConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := l.Fetcher.GetConfig()
+	if err == nil && cfg.Notifications.RunLinuxDiskEncryptionEscrow {
+		if l.mu.TryLock() {
+			defer l.mu.Unlock()
+
+			l.attemptEscrow()
+		}
+	}
+	return cfg, err
+}
+
+func (l *linuxDiskEncryptionEscrowConfigFetcher) attemptEscrow() {
+	if time.Since(l.lastRun) <= l.Frequency {
+		log.Debug().Msg("skipped LUKS passphrase escrow, last run was too recent")
+		return
+	}
+	// the user is prompted on every attempt, so failed attempts are not
+	// retried before the frequency either.
+	l.lastRun = time.Now()
+
+	if err := l.escrow(); err != nil {
+		log.Error().Err(err).Msg("escrow LUKS passphrase")
+		if serverErr := l.Escrower.SendLinuxKeyEscrowResponse(fleet.OrbitHostLUKSDataPayload{ClientError: err.Error()}); serverErr != nil {
+			log.Error().Err(serverErr).Msg("failed to send LUKS escrow failure to Fleet Server")
+		}
+		return
+	}
+	log.Info().Msg("LUKS passphrase escrowed")
+}
+
+func (l *linuxDiskEncryptionEscrowConfigFetcher) escrow() error {
+	device, err := l.rootDeviceFn()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := l.promptPassphraseFn()
+	if err != nil {
+		return err
+	}
+	newPassphrase, err := l.generatePassphraseFn()
+	if err != nil {
+		return err
+	}
+	slot, err := l.addKeyFn(device, passphrase, newPassphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := l.Escrower.SendLinuxKeyEscrowResponse(fleet.OrbitHostLUKSDataPayload{
+		Passphrase: newPassphrase,
+		KeySlot:    &slot,
+	}); err != nil {
+		// the passphrase is unknown to Fleet, do not leave it on the device.
+		if killErr := l.killSlotFn(device, slot, passphrase); killErr != nil {
+			log.Error().Err(killErr).Msgf("failed to remove LUKS key slot %d after escrow failure", slot)
+		}
+		return fmt.Errorf("send passphrase to Fleet: %w", err)
+	}
+
+	// remove the passphrase that was previously escrowed, it is replaced by
+	// the new one.
+	slotFile := filepath.Join(l.RootDir, luksKeySlotFileName)
+	if b, err := os.ReadFile(slotFile); err == nil {
+		prevSlot, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 8)
+		if err == nil && uint(prevSlot) != slot {
+			if err := l.killSlotFn(device, uint(prevSlot), passphrase); err != nil {
+				log.Error().Err(err).Msgf("failed to remove previously escrowed LUKS key slot %d", prevSlot)
+			}
+		}
+	}
+	if err := os.WriteFile(slotFile, []byte(strconv.FormatUint(uint64(slot), 10)), constant.DefaultFileMode); err != nil {
+		log.Error().Err(err).Msg("failed to record escrowed LUKS key slot")
+	}
+	return nil
+}
+
+// LinuxDiskEncryptionEnforcementReporter is the interface used to report the
+// state of the enforcement of disk encryption to the Fleet server.
+type LinuxDiskEncryptionEnforcementReporter interface {
+	SendLinuxDiskEncryptionEnforcementStatus(payload fleet.OrbitLinuxDiskEncryptionEnforcementPayload) error
+}
+
+type linuxDiskEncryptionEnforcementConfigFetcher struct {
+	// Fetcher is the OrbitConfigFetcher that will be wrapped. It is responsible
+	// for actually returning the orbit configuration or an error.
+	Fetcher OrbitConfigFetcher
+
+	// Frequency is the minimum amount of time that must pass between two
+	// times the user is guided to encrypt the disk.
+	Frequency time.Duration
+
+	// Reporter sends the enforcement state to the Fleet server.
+	Reporter LinuxDiskEncryptionEnforcementReporter
+
+	// tracks last time the user was guided
+	lastRun time.Time
+
+	// ensures only one enforcement runs at a time
+	mu sync.Mutex
+
+	// for tests, to be able to mock the LUKS operations. If nil, will use the
+	// luks package functions.
+	rootDeviceFn   func() (string, error)
+	showGuidanceFn func() error
+}
+
+// ApplyLinuxDiskEncryptionEnforcementFetcherMiddleware returns an
+// OrbitConfigFetcher that guides the user to encrypt the root volume when the
+// Fleet server sets the EnforceLinuxDiskEncryption notification.
+//
+// The root volume cannot be encrypted in place while it is mounted, so the
+// user is shown how to encrypt it and the host reports that their action is
+// required.
+func ApplyLinuxDiskEncryptionEnforcementFetcherMiddleware(
+	fetcher OrbitConfigFetcher,
+	frequency time.Duration,
+	reporter LinuxDiskEncryptionEnforcementReporter,
+) OrbitConfigFetcher {
+	return &linuxDiskEncryptionEnforcementConfigFetcher{
+		Fetcher:        fetcher,
+		Frequency:      frequency,
+		Reporter:       reporter,
+		rootDeviceFn:   luks.RootDevice,
+		showGuidanceFn: luks.ShowEncryptionGuidance,
+	}
+}
+
+// GetConfig calls the wrapped Fetcher's GetConfig method, and if the fleet
+// server set the "EnforceLinuxDiskEncryption" flag to true, guides the user
+// to encrypt the root volume.
+func (l *linuxDiskEncryptionEnforcementConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	cfg, err := l.Fetcher.GetConfig()
+	if err == nil && cfg.Notifications.EnforceLinuxDiskEncryption {
+		if l.mu.TryLock() {
+			defer l.mu.Unlock()
+
+			l.attemptEnforcement()
+		}
+	}
+	return cfg, err
+}
+
+func (l *linuxDiskEncryptionEnforcementConfigFetcher) attemptEnforcement() {
+	if time.Since(l.lastRun) <= l.Frequency {
+		log.Debug().Msg("skipped Linux disk encryption enforcement, last run was too recent")
+		return
+	}
+	l.lastRun = time.Now()
+
+	// the host may have been encrypted since osquery last reported it, the
+	// passphrase escrow takes over in that case.
+	_, err := l.rootDeviceFn()
+	switch {
+	case err == nil:
+		log.Debug().Msg("skipped Linux disk encryption enforcement, root volume is encrypted")
+		return
+	case !errors.Is(err, luks.ErrRootNotEncrypted):
+		l.report(fleet.LinuxDiskEncryptionEnforcementFailed, err)
+		return
+	}
+
+	if err := l.showGuidanceFn(); err != nil {
+		l.report(fleet.LinuxDiskEncryptionEnforcementFailed, err)
+		return
+	}
+	l.report(fleet.LinuxDiskEncryptionEnforcementActionRequired, nil)
+}
+
+func (l *linuxDiskEncryptionEnforcementConfigFetcher) report(status fleet.LinuxDiskEncryptionEnforcementStatus, err error) {
+	payload := fleet.OrbitLinuxDiskEncryptionEnforcementPayload{Status: status}
+	if err != nil {
+		log.Error().Err(err).Msg("enforce Linux disk encryption")
+		payload.Detail = err.Error()
+	}
+	if serverErr := l.Reporter.SendLinuxDiskEncryptionEnforcementStatus(payload); serverErr != nil {
+		log.Error().Err(serverErr).Msg("failed to send Linux disk encryption enforcement status to Fleet Server")
+	}
+}
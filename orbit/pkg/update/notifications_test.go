@@ -10,6 +10,11 @@ import (
 	"time"
 
 	"github.com/fleetdm/fleet/v4/orbit/pkg/bitlocker"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/fileretrieval"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/logcollection"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/luks"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/profiling"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/remotequery"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/scripts"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
@@ -810,3 +815,684 @@ func TestBitlockerOperations(t *testing.T) {
 		require.False(t, decryptFnCalled, "decryption function should not be called")
 	})
 }
+
+func TestLogCollection(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	oldLog := log.Logger
+	log.Logger = log.Output(&logBuf)
+	t.Cleanup(func() { log.Logger = oldLog })
+
+	var (
+		callsCount atomic.Int64
+		runFailure error
+		gotEnabled atomic.Bool
+	)
+
+	mockRun := func(c *logcollection.Collector, ids []string) error {
+		callsCount.Add(1)
+		gotEnabled.Store(c.Enabled)
+		return runFailure
+	}
+
+	waitForRun := func(t *testing.T, f *logCollectionConfigFetcher) {
+		var ok bool
+		for start := time.Now(); !ok && time.Since(start) < time.Second; {
+			ok = f.mu.TryLock()
+		}
+		require.True(t, ok, "timed out waiting for the lock to become available")
+		f.mu.Unlock()
+	}
+
+	t.Run("no pending log collections", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{}},
+		}
+		f := &logCollectionConfigFetcher{
+			Fetcher:              fetcher,
+			LogCollectionEnabled: func() bool { return true },
+			runLogCollectionFn:   mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		require.True(t, f.mu.TryLock())
+		require.Zero(t, callsCount.Load())
+		require.Empty(t, logBuf.String())
+	})
+
+	t.Run("pending log collections succeed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingLogCollectionIDs: []string{"a", "b"},
+			}},
+		}
+		f := &logCollectionConfigFetcher{
+			Fetcher:              fetcher,
+			LogCollectionEnabled: func() bool { return true },
+			runLogCollectionFn:   mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.True(t, gotEnabled.Load())
+		require.Contains(t, logBuf.String(), "received request to collect logs [a b]")
+		require.Contains(t, logBuf.String(), "collecting logs [a b] succeeded")
+	})
+
+	t.Run("pending log collections failed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset(); runFailure = nil })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingLogCollectionIDs: []string{"a"},
+			}},
+		}
+		runFailure = io.ErrUnexpectedEOF
+		f := &logCollectionConfigFetcher{
+			Fetcher:              fetcher,
+			LogCollectionEnabled: func() bool { return false },
+			runLogCollectionFn:   mockRun,
+		}
+		_, err := f.GetConfig()
+		require.NoError(t, err)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.False(t, gotEnabled.Load())
+		require.Contains(t, logBuf.String(), "collecting logs failed")
+		require.Contains(t, logBuf.String(), io.ErrUnexpectedEOF.Error())
+	})
+}
+
+func TestFileRetrieval(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	oldLog := log.Logger
+	log.Logger = log.Output(&logBuf)
+	t.Cleanup(func() { log.Logger = oldLog })
+
+	var (
+		callsCount atomic.Int64
+		runFailure error
+		gotEnabled atomic.Bool
+	)
+
+	mockRun := func(r *fileretrieval.Retriever, ids []string) error {
+		callsCount.Add(1)
+		gotEnabled.Store(r.Enabled)
+		return runFailure
+	}
+
+	waitForRun := func(t *testing.T, f *fileRetrievalConfigFetcher) {
+		var ok bool
+		for start := time.Now(); !ok && time.Since(start) < time.Second; {
+			ok = f.mu.TryLock()
+		}
+		require.True(t, ok, "timed out waiting for the lock to become available")
+		f.mu.Unlock()
+	}
+
+	t.Run("no pending file retrievals", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{}},
+		}
+		f := &fileRetrievalConfigFetcher{
+			Fetcher:              fetcher,
+			FileRetrievalEnabled: func() bool { return true },
+			runFileRetrievalFn:   mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		require.True(t, f.mu.TryLock())
+		require.Zero(t, callsCount.Load())
+		require.Empty(t, logBuf.String())
+	})
+
+	t.Run("pending file retrievals succeed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingFileRetrievalIDs: []string{"a", "b"},
+			}},
+		}
+		f := &fileRetrievalConfigFetcher{
+			Fetcher:              fetcher,
+			FileRetrievalEnabled: func() bool { return true },
+			runFileRetrievalFn:   mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.True(t, gotEnabled.Load())
+		require.Contains(t, logBuf.String(), "received request to retrieve files [a b]")
+		require.Contains(t, logBuf.String(), "retrieving files [a b] succeeded")
+	})
+
+	t.Run("pending file retrievals failed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset(); runFailure = nil })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingFileRetrievalIDs: []string{"a"},
+			}},
+		}
+		runFailure = io.ErrUnexpectedEOF
+		f := &fileRetrievalConfigFetcher{
+			Fetcher:              fetcher,
+			FileRetrievalEnabled: func() bool { return false },
+			runFileRetrievalFn:   mockRun,
+		}
+		_, err := f.GetConfig()
+		require.NoError(t, err)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.False(t, gotEnabled.Load())
+		require.Contains(t, logBuf.String(), "retrieving files failed")
+		require.Contains(t, logBuf.String(), io.ErrUnexpectedEOF.Error())
+	})
+}
+
+func TestPprofCapture(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	oldLog := log.Logger
+	log.Logger = log.Output(&logBuf)
+	t.Cleanup(func() { log.Logger = oldLog })
+
+	var (
+		callsCount atomic.Int64
+		runFailure error
+	)
+
+	mockRun := func(c *profiling.Capturer, ids []string) error {
+		callsCount.Add(1)
+		return runFailure
+	}
+
+	waitForRun := func(t *testing.T, f *pprofCaptureConfigFetcher) {
+		var ok bool
+		for start := time.Now(); !ok && time.Since(start) < time.Second; {
+			ok = f.mu.TryLock()
+		}
+		require.True(t, ok, "timed out waiting for the lock to become available")
+		f.mu.Unlock()
+	}
+
+	t.Run("no pending pprof captures", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{}},
+		}
+		f := &pprofCaptureConfigFetcher{
+			Fetcher:           fetcher,
+			runPprofCaptureFn: mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		require.True(t, f.mu.TryLock())
+		require.Zero(t, callsCount.Load())
+		require.Empty(t, logBuf.String())
+	})
+
+	t.Run("pending pprof captures succeed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingPprofCaptureIDs: []string{"a", "b"},
+			}},
+		}
+		f := &pprofCaptureConfigFetcher{
+			Fetcher:           fetcher,
+			runPprofCaptureFn: mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.Contains(t, logBuf.String(), "received request to capture pprof profiles [a b]")
+		require.Contains(t, logBuf.String(), "capturing pprof profiles [a b] succeeded")
+	})
+
+	t.Run("pending pprof captures failed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset(); runFailure = nil })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingPprofCaptureIDs: []string{"a"},
+			}},
+		}
+		runFailure = io.ErrUnexpectedEOF
+		f := &pprofCaptureConfigFetcher{
+			Fetcher:           fetcher,
+			runPprofCaptureFn: mockRun,
+		}
+		_, err := f.GetConfig()
+		require.NoError(t, err)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.Contains(t, logBuf.String(), "capturing pprof profiles failed")
+		require.Contains(t, logBuf.String(), io.ErrUnexpectedEOF.Error())
+	})
+}
+
+func TestRemoteQuery(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	oldLog := log.Logger
+	log.Logger = log.Output(&logBuf)
+	t.Cleanup(func() { log.Logger = oldLog })
+
+	var (
+		callsCount atomic.Int64
+		runFailure error
+	)
+
+	mockRun := func(c *remotequery.Runner, ids []string) error {
+		callsCount.Add(1)
+		return runFailure
+	}
+
+	waitForRun := func(t *testing.T, f *remoteQueryConfigFetcher) {
+		var ok bool
+		for start := time.Now(); !ok && time.Since(start) < time.Second; {
+			ok = f.mu.TryLock()
+		}
+		require.True(t, ok, "timed out waiting for the lock to become available")
+		f.mu.Unlock()
+	}
+
+	t.Run("no pending remote queries", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{}},
+		}
+		f := &remoteQueryConfigFetcher{
+			Fetcher:          fetcher,
+			runRemoteQueryFn: mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		require.True(t, f.mu.TryLock())
+		require.Zero(t, callsCount.Load())
+		require.Empty(t, logBuf.String())
+	})
+
+	t.Run("pending remote queries succeed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset() })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingRemoteQueryIDs: []string{"a", "b"},
+			}},
+		}
+		f := &remoteQueryConfigFetcher{
+			Fetcher:          fetcher,
+			runRemoteQueryFn: mockRun,
+		}
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.Contains(t, logBuf.String(), "received request to run remote queries [a b]")
+		require.Contains(t, logBuf.String(), "running remote queries [a b] succeeded")
+	})
+
+	t.Run("pending remote queries failed", func(t *testing.T) {
+		t.Cleanup(func() { callsCount.Store(0); logBuf.Reset(); runFailure = nil })
+
+		fetcher := &dummyConfigFetcher{
+			cfg: &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{
+				PendingRemoteQueryIDs: []string{"a"},
+			}},
+		}
+		runFailure = io.ErrUnexpectedEOF
+		f := &remoteQueryConfigFetcher{
+			Fetcher:          fetcher,
+			runRemoteQueryFn: mockRun,
+		}
+		_, err := f.GetConfig()
+		require.NoError(t, err)
+
+		waitForRun(t, f)
+		require.Equal(t, int64(1), callsCount.Load())
+		require.Contains(t, logBuf.String(), "running remote queries failed")
+		require.Contains(t, logBuf.String(), io.ErrUnexpectedEOF.Error())
+	})
+}
+
+type mockWindowsServiceConfigurer struct {
+	heartbeats int
+	applied    []*fleet.OrbitWindowsServiceConfig
+	err        error
+}
+
+func (m *mockWindowsServiceConfigurer) Heartbeat() {
+	m.heartbeats++
+}
+
+func (m *mockWindowsServiceConfigurer) ApplyConfig(cfg *fleet.OrbitWindowsServiceConfig) error {
+	m.applied = append(m.applied, cfg)
+	return m.err
+}
+
+type errConfigFetcher struct {
+	err error
+}
+
+func (e *errConfigFetcher) GetConfig() (*fleet.OrbitConfig, error) {
+	return nil, e.err
+}
+
+func TestWindowsServiceConfig(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	oldLog := log.Logger
+	log.Logger = log.Output(&logBuf)
+	t.Cleanup(func() { log.Logger = oldLog })
+
+	wsCfg := &fleet.OrbitWindowsServiceConfig{
+		RecoveryActions:          []fleet.OrbitServiceRecoveryAction{{Type: fleet.OrbitServiceRecoveryActionRestart}},
+		WatchdogHeartbeatSeconds: 300,
+	}
+
+	t.Run("config applied", func(t *testing.T) {
+		configurer := &mockWindowsServiceConfigurer{}
+		fetcher := &dummyConfigFetcher{cfg: &fleet.OrbitConfig{WindowsService: wsCfg}}
+		f := ApplyWindowsServiceConfigFetcherMiddleware(fetcher, configurer)
+
+		cfg, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+		require.Equal(t, 1, configurer.heartbeats)
+		require.Equal(t, []*fleet.OrbitWindowsServiceConfig{wsCfg}, configurer.applied)
+	})
+
+	t.Run("apply failure is logged", func(t *testing.T) {
+		t.Cleanup(logBuf.Reset)
+
+		configurer := &mockWindowsServiceConfigurer{err: io.ErrUnexpectedEOF}
+		fetcher := &dummyConfigFetcher{cfg: &fleet.OrbitConfig{WindowsService: wsCfg}}
+		f := ApplyWindowsServiceConfigFetcherMiddleware(fetcher, configurer)
+
+		_, err := f.GetConfig()
+		require.NoError(t, err)
+		require.Len(t, configurer.applied, 1)
+		require.Contains(t, logBuf.String(), "applying windows service config failed")
+	})
+
+	t.Run("heartbeat on fetch failure", func(t *testing.T) {
+		configurer := &mockWindowsServiceConfigurer{}
+		f := ApplyWindowsServiceConfigFetcherMiddleware(&errConfigFetcher{err: io.ErrUnexpectedEOF}, configurer)
+
+		_, err := f.GetConfig()
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		require.Equal(t, 1, configurer.heartbeats)
+		require.Empty(t, configurer.applied)
+	})
+}
+
+func TestFeatureFlagsConfigFetcher(t *testing.T) {
+	var flags FeatureFlags
+	require.False(t, flags.Enabled("installer_queue"))
+
+	fetcher := &dummyConfigFetcher{
+		cfg: &fleet.OrbitConfig{FeatureFlags: map[string]bool{"installer_queue": true, "new_tables": false}},
+	}
+	cfg, err := ApplyFeatureFlagsConfigFetcherMiddleware(fetcher, &flags).GetConfig()
+	require.NoError(t, err)
+	require.Equal(t, fetcher.cfg, cfg)
+	require.True(t, flags.Enabled("installer_queue"))
+	require.False(t, flags.Enabled("new_tables"))
+	require.False(t, flags.Enabled("unknown"))
+
+	// the last received flags are kept if the config can't be fetched
+	_, err = ApplyFeatureFlagsConfigFetcherMiddleware(&errConfigFetcher{err: io.ErrUnexpectedEOF}, &flags).GetConfig()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.True(t, flags.Enabled("installer_queue"))
+
+	// the flags are removed when the server stops sending them
+	fetcher.cfg = &fleet.OrbitConfig{}
+	_, err = ApplyFeatureFlagsConfigFetcherMiddleware(fetcher, &flags).GetConfig()
+	require.NoError(t, err)
+	require.False(t, flags.Enabled("installer_queue"))
+}
+
+type mockLinuxDiskEncryptionKeyEscrower struct {
+	payloads []fleet.OrbitHostLUKSDataPayload
+	err      error
+}
+
+func (m *mockLinuxDiskEncryptionKeyEscrower) SendLinuxKeyEscrowResponse(payload fleet.OrbitHostLUKSDataPayload) error {
+	m.payloads = append(m.payloads, payload)
+	if payload.ClientError == "" {
+		return m.err
+	}
+	return nil
+}
+
+func TestLinuxDiskEncryptionEscrow(t *testing.T) {
+	fetcher := &dummyConfigFetcher{
+		cfg: &fleet.OrbitConfig{
+			Notifications: fleet.OrbitConfigNotifications{
+				RunLinuxDiskEncryptionEscrow: true,
+			},
+		},
+	}
+
+	var (
+		escrower    *mockLinuxDiskEncryptionKeyEscrower
+		promptErr   error
+		nextSlot    uint
+		killedSlots []uint
+		escrowFn    *linuxDiskEncryptionEscrowConfigFetcher
+		rootDir     string
+	)
+	setupTest := func() {
+		escrower = &mockLinuxDiskEncryptionKeyEscrower{}
+		promptErr = nil
+		killedSlots = nil
+		rootDir = t.TempDir()
+		escrowFn = &linuxDiskEncryptionEscrowConfigFetcher{
+			Fetcher:   fetcher,
+			Frequency: time.Hour,
+			Escrower:  escrower,
+			RootDir:   rootDir,
+			rootDeviceFn: func() (string, error) {
+				return "/dev/sda2", nil
+			},
+			promptPassphraseFn: func() (string, error) {
+				return "user-passphrase", promptErr
+			},
+			generatePassphraseFn: func() (string, error) {
+				return "fleet-passphrase", nil
+			},
+			addKeyFn: func(device, passphrase, newPassphrase string) (uint, error) {
+				require.Equal(t, "/dev/sda2", device)
+				require.Equal(t, "user-passphrase", passphrase)
+				require.Equal(t, "fleet-passphrase", newPassphrase)
+				return nextSlot, nil
+			},
+			killSlotFn: func(device string, slot uint, passphrase string) error {
+				require.Equal(t, "user-passphrase", passphrase)
+				killedSlots = append(killedSlots, slot)
+				return nil
+			},
+		}
+	}
+
+	t.Run("passphrase is escrowed and rotated", func(t *testing.T) {
+		setupTest()
+		nextSlot = 1
+		cfg, err := escrowFn.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+		require.Equal(t, []fleet.OrbitHostLUKSDataPayload{{Passphrase: "fleet-passphrase", KeySlot: ptr.Uint(1)}}, escrower.payloads)
+		require.Empty(t, killedSlots)
+
+		// a run within the frequency is skipped
+		_, err = escrowFn.GetConfig()
+		require.NoError(t, err)
+		require.Len(t, escrower.payloads, 1)
+
+		// the previously escrowed slot is removed on rotation
+		escrowFn.lastRun = time.Now().Add(-2 * time.Hour)
+		nextSlot = 2
+		_, err = escrowFn.GetConfig()
+		require.NoError(t, err)
+		require.Len(t, escrower.payloads, 2)
+		require.Equal(t, ptr.Uint(2), escrower.payloads[1].KeySlot)
+		require.Equal(t, []uint{1}, killedSlots)
+	})
+
+	t.Run("user cancels the prompt", func(t *testing.T) {
+		setupTest()
+		promptErr = errors.New("cancelled")
+		_, err := escrowFn.GetConfig()
+		require.NoError(t, err)
+		require.Len(t, escrower.payloads, 1)
+		require.Equal(t, "cancelled", escrower.payloads[0].ClientError)
+		require.Empty(t, escrower.payloads[0].Passphrase)
+	})
+
+	t.Run("new key slot is removed if the escrow fails", func(t *testing.T) {
+		setupTest()
+		nextSlot = 3
+		escrower.err = errors.New("server error")
+		_, err := escrowFn.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, []uint{3}, killedSlots)
+		require.Len(t, escrower.payloads, 2)
+		require.Contains(t, escrower.payloads[1].ClientError, "server error")
+	})
+}
+
+type mockLinuxDiskEncryptionEnforcementReporter struct {
+	payloads []fleet.OrbitLinuxDiskEncryptionEnforcementPayload
+}
+
+func (m *mockLinuxDiskEncryptionEnforcementReporter) SendLinuxDiskEncryptionEnforcementStatus(payload fleet.OrbitLinuxDiskEncryptionEnforcementPayload) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+func TestLinuxDiskEncryptionEnforcement(t *testing.T) {
+	fetcher := &dummyConfigFetcher{
+		cfg: &fleet.OrbitConfig{
+			Notifications: fleet.OrbitConfigNotifications{
+				EnforceLinuxDiskEncryption: true,
+			},
+		},
+	}
+
+	var (
+		reporter      *mockLinuxDiskEncryptionEnforcementReporter
+		rootDeviceErr error
+		guidanceErr   error
+		guidanceShown int
+		enforceFn     *linuxDiskEncryptionEnforcementConfigFetcher
+	)
+	setupTest := func() {
+		reporter = &mockLinuxDiskEncryptionEnforcementReporter{}
+		rootDeviceErr = luks.ErrRootNotEncrypted
+		guidanceErr = nil
+		guidanceShown = 0
+		enforceFn = &linuxDiskEncryptionEnforcementConfigFetcher{
+			Fetcher:   fetcher,
+			Frequency: time.Hour,
+			Reporter:  reporter,
+			rootDeviceFn: func() (string, error) {
+				return "", rootDeviceErr
+			},
+			showGuidanceFn: func() error {
+				guidanceShown++
+				return guidanceErr
+			},
+		}
+	}
+
+	t.Run("user is guided to encrypt the disk", func(t *testing.T) {
+		setupTest()
+		cfg, err := enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, fetcher.cfg, cfg)
+		require.Equal(t, 1, guidanceShown)
+		require.Equal(t, []fleet.OrbitLinuxDiskEncryptionEnforcementPayload{
+			{Status: fleet.LinuxDiskEncryptionEnforcementActionRequired},
+		}, reporter.payloads)
+
+		// a run within the frequency is skipped
+		_, err = enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, 1, guidanceShown)
+		require.Len(t, reporter.payloads, 1)
+	})
+
+	t.Run("root volume is already encrypted", func(t *testing.T) {
+		setupTest()
+		rootDeviceErr = nil
+		_, err := enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Zero(t, guidanceShown)
+		require.Empty(t, reporter.payloads)
+	})
+
+	t.Run("guidance cannot be shown", func(t *testing.T) {
+		setupTest()
+		guidanceErr = errors.New("no graphical session")
+		_, err := enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Equal(t, []fleet.OrbitLinuxDiskEncryptionEnforcementPayload{
+			{Status: fleet.LinuxDiskEncryptionEnforcementFailed, Detail: "no graphical session"},
+		}, reporter.payloads)
+	})
+
+	t.Run("root volume cannot be inspected", func(t *testing.T) {
+		setupTest()
+		rootDeviceErr = errors.New("findmnt not found")
+		_, err := enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Zero(t, guidanceShown)
+		require.Equal(t, []fleet.OrbitLinuxDiskEncryptionEnforcementPayload{
+			{Status: fleet.LinuxDiskEncryptionEnforcementFailed, Detail: "findmnt not found"},
+		}, reporter.payloads)
+	})
+
+	t.Run("notification is not set", func(t *testing.T) {
+		setupTest()
+		enforceFn.Fetcher = &dummyConfigFetcher{cfg: &fleet.OrbitConfig{}}
+		_, err := enforceFn.GetConfig()
+		require.NoError(t, err)
+		require.Zero(t, guidanceShown)
+		require.Empty(t, reporter.payloads)
+	})
+}
@@ -24,7 +24,9 @@ type MDMMigrator interface {
 // MDMMigratorProps are props required to display the dialog. It's akin to the
 // concept of props in UI frameworks like React.
 type MDMMigratorProps struct {
-	OrgInfo     fleet.DesktopOrgInfo
+	OrgInfo fleet.DesktopOrgInfo
+	// Messages are the localized messages of the dialog.
+	Messages    fleet.LocalizedMessages
 	IsUnmanaged bool
 }
 
@@ -14,6 +14,7 @@ import (
 
 	"github.com/fleetdm/fleet/v4/pkg/file"
 	"github.com/fleetdm/fleet/v4/pkg/retry"
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/rs/zerolog/log"
 )
 
@@ -49,17 +50,17 @@ const mdmUnenrollmentTotalWaitTime = 90 * time.Second
 const defaultUnenrollmentRetryInterval = 5 * time.Second
 
 var mdmMigrationTemplate = template.Must(template.New("mdmMigrationTemplate").Parse(`
-## Migrate to Fleet
+## {{ .Messages.Get "mdm_migration.title" }}
 
-Select **Start** and look for this notification in your notification center:` +
+{{ .Messages.Get "mdm_migration.instructions" }}` +
 	"\n\n![Image showing MDM migration notification](https://fleetdm.com/images/permanent/mdm-migration-screenshot-notification-2048x480.png)\n\n" +
-	"After you start, this window will popup every 15-20 minutes until you finish.",
+	`{{ .Messages.Get "mdm_migration.reminder" }}`,
 ))
 
 var errorTemplate = template.Must(template.New("").Parse(`
-### Something's gone wrong.
+### {{ .Messages.Get "mdm_migration.error_title" }}
 
-Please contact your IT admin [here]({{ .ContactURL }}).
+{{ .Messages.Format "mdm_migration.error_contact" "contact_url" .OrgInfo.ContactURL }}
 `))
 
 // baseDialog implements the basic building blocks to render dialogs using
@@ -235,8 +236,9 @@ func (m *swiftDialogMDMMigrator) render(message string, flags ...string) (chan s
 }
 
 func (m *swiftDialogMDMMigrator) renderLoadingSpinner() (chan swiftDialogExitCode, chan error) {
-	return m.render("## Migrate to Fleet\nUnenrolling you from your old MDM. This could take 90 seconds...",
-		"--button1text", "Start",
+	msgs := m.props.Messages
+	return m.render("## "+msgs.Get(fleet.MessageMDMMigrationTitle)+"\n"+msgs.Get(fleet.MessageMDMMigrationUnenrolling),
+		"--button1text", msgs.Get(fleet.MessageMDMMigrationStart),
 		"--button1disabled",
 		"--quitkey", "x",
 		"--height", "220",
@@ -247,7 +249,7 @@ func (m *swiftDialogMDMMigrator) renderError() (chan swiftDialogExitCode, chan e
 	var errorMessage bytes.Buffer
 	if err := errorTemplate.Execute(
 		&errorMessage,
-		m.props.OrgInfo,
+		m.props,
 	); err != nil {
 		codeChan := make(chan swiftDialogExitCode, 1)
 		errChan := make(chan error, 1)
@@ -255,7 +257,7 @@ func (m *swiftDialogMDMMigrator) renderError() (chan swiftDialogExitCode, chan e
 		return codeChan, errChan
 	}
 
-	return m.render(errorMessage.String(), "--button1text", "Close", "--height", "220")
+	return m.render(errorMessage.String(), "--button1text", m.props.Messages.Get(fleet.MessageMDMMigrationClose), "--height", "220")
 }
 
 // waitForUnenrollment waits 90 seconds (value determined by product) for the
@@ -301,16 +303,16 @@ func (m *swiftDialogMDMMigrator) renderMigration() error {
 
 	flags := []string{
 		// main button
-		"--button1text", "Start",
+		"--button1text", m.props.Messages.Get(fleet.MessageMDMMigrationStart),
 		// secondary button
-		"--button2text", "Later",
+		"--button2text", m.props.Messages.Get(fleet.MessageMDMMigrationLater),
 		"--height", "440",
 	}
 
 	if m.props.OrgInfo.ContactURL != "" {
 		flags = append(flags,
 			// info button
-			"--infobuttontext", "Unsure? Contact IT",
+			"--infobuttontext", m.props.Messages.Get(fleet.MessageMDMMigrationContactIT),
 			"--infobuttonaction", m.props.OrgInfo.ContactURL,
 			"--quitoninfo",
 		)
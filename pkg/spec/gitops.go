@@ -57,11 +57,24 @@ type GitOps struct {
 
 // GitOpsFromBytes parses a GitOps yaml file.
 func GitOpsFromBytes(b []byte, baseDir string) (*GitOps, error) {
+	return GitOpsFromBytesWithOverlay(b, nil, baseDir)
+}
+
+// GitOpsFromBytesWithOverlay parses a GitOps yaml file patched with the
+// provided environment overlay (see GitOpsEnvOverlayPath). A nil overlay
+// parses the file as is.
+func GitOpsFromBytesWithOverlay(b []byte, overlay []byte, baseDir string) (*GitOps, error) {
 	var top map[string]json.RawMessage
 	b = []byte(os.ExpandEnv(string(b))) // replace $var and ${var} with env values
 	if err := yaml.Unmarshal(b, &top); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal file %w: \n", err)
 	}
+	if overlay != nil {
+		var err error
+		if top, err = applyEnvOverlay(top, overlay, baseDir); err != nil {
+			return nil, err
+		}
+	}
 
 	var multiError *multierror.Error
 	result := &GitOps{}
@@ -0,0 +1,123 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+var gitOpsEnvRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateGitOpsEnv checks that env is a valid environment name, i.e. that it
+// only contains letters, digits, dashes and underscores.
+func ValidateGitOpsEnv(env string) error {
+	if !gitOpsEnvRegexp.MatchString(env) {
+		return fmt.Errorf("invalid environment %q: only letters, digits, '-' and '_' are allowed", env)
+	}
+	return nil
+}
+
+// GitOpsEnvOverlayPath returns the path of the overlay of the GitOps file for
+// the environment, next to the file and named after it, e.g.
+// teams/workstations.prod.yml for teams/workstations.yml and the prod
+// environment.
+func GitOpsEnvOverlayPath(filename string, env string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "." + env + ext
+}
+
+// applyEnvOverlay patches the top-level fields of a GitOps file with the ones
+// of the environment overlay. The mappings are merged recursively, the other
+// values (including lists and null) replace the base values. A top-level
+// field of the base file that refers to another file via its path is read to
+// be patched, while a field of the overlay that refers to another file
+// replaces the base field.
+func applyEnvOverlay(top map[string]json.RawMessage, overlay []byte, baseDir string) (map[string]json.RawMessage, error) {
+	overlay = []byte(os.ExpandEnv(string(overlay)))
+	var patch map[string]json.RawMessage
+	if err := yaml.Unmarshal(overlay, &patch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal environment overlay: %w", err)
+	}
+	if top == nil {
+		top = make(map[string]json.RawMessage, len(patch))
+	}
+
+	for key, patchRaw := range patch {
+		var patchVal interface{}
+		if err := json.Unmarshal(patchRaw, &patchVal); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal environment overlay %s: %w", key, err)
+		}
+		patchMap, ok := patchVal.(map[string]interface{})
+		if _, isPath := patchMap["path"]; !ok || isPath {
+			top[key] = patchRaw
+			continue
+		}
+
+		var baseVal interface{}
+		if baseRaw, ok := top[key]; ok {
+			if err := json.Unmarshal(baseRaw, &baseVal); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+			}
+		}
+		baseMap, ok := baseVal.(map[string]interface{})
+		if !ok {
+			top[key] = patchRaw
+			continue
+		}
+		if path, ok := baseMap["path"].(string); ok {
+			var err error
+			if baseMap, err = readGitOpsMapFile(key, path, baseDir); err != nil {
+				return nil, err
+			}
+		}
+
+		merged, err := json.Marshal(mergeGitOpsMaps(baseMap, patchMap))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+		top[key] = merged
+	}
+	return top, nil
+}
+
+// readGitOpsMapFile reads the file that a top-level field refers to.
+func readGitOpsMapFile(key string, path string, baseDir string) (map[string]interface{}, error) {
+	fileBytes, err := os.ReadFile(resolveApplyRelativePath(baseDir, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file %s: %v", key, path, err)
+	}
+	fileBytes = []byte(os.ExpandEnv(string(fileBytes)))
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(fileBytes, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s file %s: %v", key, path, err)
+	}
+	if nested, ok := m["path"]; ok {
+		return nil, fmt.Errorf("nested paths are not supported: %v in %s", nested, path)
+	}
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	return m, nil
+}
+
+// mergeGitOpsMaps returns base recursively patched with patch.
+func mergeGitOpsMaps(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		patchMap, ok := v.(map[string]interface{})
+		if baseMap, isMap := merged[k].(map[string]interface{}); ok && isMap {
+			merged[k] = mergeGitOpsMaps(baseMap, patchMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
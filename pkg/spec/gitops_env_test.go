@@ -0,0 +1,95 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGitOpsEnv(t *testing.T) {
+	t.Parallel()
+	for _, env := range []string{"prod", "staging-2", "eu_west"} {
+		assert.NoError(t, ValidateGitOpsEnv(env), env)
+	}
+	for _, env := range []string{"", "../prod", "prod.yml", "pr od"} {
+		assert.Error(t, ValidateGitOpsEnv(env), env)
+	}
+}
+
+func TestGitOpsEnvOverlayPath(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "default.prod.yml", GitOpsEnvOverlayPath("default.yml", "prod"))
+	assert.Equal(t, "teams/workstations.staging.yaml", GitOpsEnvOverlayPath("teams/workstations.yaml", "staging"))
+	assert.Equal(t, "gitops.prod", GitOpsEnvOverlayPath("gitops", "prod"))
+}
+
+func TestGitOpsFromBytesWithOverlay(t *testing.T) {
+	base, err := os.ReadFile("testdata/team_config.yml")
+	require.NoError(t, err)
+
+	// no overlay
+	gitops, err := GitOpsFromBytesWithOverlay(base, nil, "./testdata")
+	require.NoError(t, err)
+	assert.Equal(t, "Team1", *gitops.TeamName)
+
+	// the team name and the team settings read from their file are patched,
+	// the lists are replaced
+	gitops, err = GitOpsFromBytesWithOverlay(base, []byte(`
+name: Team1 (staging)
+team_settings:
+  secrets:
+    - secret: "StagingSecret"
+  webhook_settings:
+    failing_policies_webhook:
+      destination_url: https://staging.example.com/webhook
+  host_expiry_settings:
+policies:
+`), "./testdata")
+	require.NoError(t, err)
+	assert.Equal(t, "Team1 (staging)", *gitops.TeamName)
+	require.Len(t, gitops.TeamSettings["secrets"], 1)
+	webhook := gitops.TeamSettings["webhook_settings"].(map[string]interface{})["failing_policies_webhook"].(map[string]interface{})
+	assert.Equal(t, "https://staging.example.com/webhook", webhook["destination_url"])
+	assert.Equal(t, true, webhook["enable_failing_policies_webhook"])
+	assert.Nil(t, gitops.TeamSettings["host_expiry_settings"])
+	assert.Equal(t, map[string]interface{}{"enable_host_users": true, "enable_software_inventory": true}, gitops.TeamSettings["features"])
+	assert.Empty(t, gitops.Policies)
+	// the fields not in the overlay are unchanged
+	assert.Len(t, gitops.Queries, 3)
+
+	// a field of the overlay can refer to another file
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "agent-options.prod.yml"), []byte(`
+config:
+  options:
+    distributed_interval: 10
+`), 0o644))
+	gitops, err = GitOpsFromBytesWithOverlay(base, []byte(`
+agent_options:
+  path: `+filepath.Join(dir, "agent-options.prod.yml")+`
+`), "./testdata")
+	require.NoError(t, err)
+	assert.Contains(t, string(*gitops.AgentOptions), `"distributed_interval":10`)
+
+	// environment variables are expanded in the overlay
+	t.Setenv("GITOPS_TEST_TEAM_NAME", "Team1 (prod)")
+	gitops, err = GitOpsFromBytesWithOverlay(base, []byte(`name: $GITOPS_TEST_TEAM_NAME`), "./testdata")
+	require.NoError(t, err)
+	assert.Equal(t, "Team1 (prod)", *gitops.TeamName)
+
+	// the overlay is validated like the base file
+	_, err = GitOpsFromBytesWithOverlay(base, []byte(`unknown: true`), "./testdata")
+	assert.ErrorContains(t, err, "unknown top-level field: unknown")
+	_, err = GitOpsFromBytesWithOverlay(base, []byte(`[not, a, mapping]`), "./testdata")
+	assert.ErrorContains(t, err, "failed to unmarshal environment overlay")
+
+	// the base file that an overlay patches must exist
+	_, err = GitOpsFromBytesWithOverlay(base, []byte(`
+controls:
+  enable_disk_encryption: true
+`), dir)
+	assert.ErrorContains(t, err, "failed to read controls file ./controls.yml")
+}
@@ -27,6 +27,8 @@ type Group struct {
 	AppConfig    interface{}
 	EnrollSecret *fleet.EnrollSecretSpec
 	UsersRoles   *fleet.UsersRoleSpec
+	// HostCustomFields holds the notes and custom field values of hosts.
+	HostCustomFields *fleet.HostCustomFieldsSpec
 }
 
 // Metadata holds the metadata for a single YAML section/item.
@@ -112,6 +114,17 @@ func GroupFromBytes(b []byte) (*Group, error) {
 			}
 			specs.UsersRoles = userRoleSpec
 
+		case fleet.HostCustomFieldsKind:
+			if specs.HostCustomFields != nil {
+				return nil, errors.New("host_custom_fields defined twice in the same file")
+			}
+
+			var hostCustomFieldsSpec *fleet.HostCustomFieldsSpec
+			if err := yaml.Unmarshal(s.Spec, &hostCustomFieldsSpec); err != nil {
+				return nil, fmt.Errorf("unmarshaling %s spec: %w", kind, err)
+			}
+			specs.HostCustomFields = hostCustomFieldsSpec
+
 		case fleet.TeamKind:
 			// unmarshal to a raw map as we don't want to strip away unknown/invalid
 			// fields at this point - that validation is done in the apply spec/teams
@@ -0,0 +1,166 @@
+// Package spreadsheet provides writers that stream tabular records to CSV or
+// XLSX (Office Open XML spreadsheet) files, without holding the whole file in
+// memory.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writer writes records, the first one is typically the header. Close must
+// be called to flush the file, it does not close the underlying io.Writer.
+type Writer interface {
+	Write(record []string) error
+	Close() error
+}
+
+// NewCSVWriter returns a Writer that writes CSV records to w.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(record []string) error {
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// maxSheetNameLength is the maximum length of a worksheet name supported by
+// spreadsheet applications.
+const maxSheetNameLength = 31
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// NewXLSXWriter returns a Writer that writes the records to a single-sheet
+// XLSX file. All cells are written as strings.
+func NewXLSXWriter(w io.Writer, sheetName string) (Writer, error) {
+	zw := zip.NewWriter(w)
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sanitizeSheetName(sheetName))); err != nil {
+		return nil, fmt.Errorf("escape sheet name: %w", err)
+	}
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("create %s: %w", part.name, err)
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return nil, fmt.Errorf("write %s: %w", part.name, err)
+		}
+	}
+
+	// the sheet is the last part of the archive, its rows are streamed to it.
+	sw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("create sheet: %w", err)
+	}
+	bw := bufio.NewWriter(sw)
+	if _, err := bw.WriteString(xlsxSheetHeader); err != nil {
+		return nil, fmt.Errorf("write sheet header: %w", err)
+	}
+	return &xlsxWriter{zw: zw, w: bw}, nil
+}
+
+type xlsxWriter struct {
+	zw     *zip.Writer
+	w      *bufio.Writer
+	closed bool
+}
+
+func (x *xlsxWriter) Write(record []string) error {
+	if x.closed {
+		return errors.New("write to closed XLSX writer")
+	}
+	if _, err := x.w.WriteString("<row>"); err != nil {
+		return err
+	}
+	for _, value := range record {
+		if _, err := x.w.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		// invalid XML characters are replaced by EscapeText.
+		if err := xml.EscapeText(x.w, []byte(value)); err != nil {
+			return err
+		}
+		if _, err := x.w.WriteString("</t></is></c>"); err != nil {
+			return err
+		}
+	}
+	_, err := x.w.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	if _, err := x.w.WriteString(xlsxSheetFooter); err != nil {
+		return fmt.Errorf("write sheet footer: %w", err)
+	}
+	if err := x.w.Flush(); err != nil {
+		return fmt.Errorf("flush sheet: %w", err)
+	}
+	return x.zw.Close()
+}
+
+// sanitizeSheetName removes the characters that are not allowed in worksheet
+// names and truncates the name to the maximum length.
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', '/', '?', '*', '[', ']', ':':
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if runes := []rune(name); len(runes) > maxSheetNameLength {
+		name = string(runes[:maxSheetNameLength])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
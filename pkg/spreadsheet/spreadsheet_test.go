@@ -0,0 +1,82 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	require.NoError(t, w.Write([]string{"id", "name"}))
+	require.NoError(t, w.Write([]string{"1", "a, \"quoted\" name"}))
+	require.NoError(t, w.Close())
+	require.Equal(t, "id,name\n1,\"a, \"\"quoted\"\" name\"\n", buf.String())
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf, "Hosts: [all]")
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]string{"id", "name"}))
+	require.NoError(t, w.Write([]string{"1", "<Anna's> & \x01Mac"}))
+	require.NoError(t, w.Write(nil))
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	require.Error(t, w.Write([]string{"2"}))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = b
+	}
+	require.Len(t, files, 5)
+	require.Contains(t, string(files["xl/workbook.xml"]), `<sheet name="Hosts all" sheetId="1" r:id="rId1"/>`)
+
+	// all parts are well-formed XML
+	for name, b := range files {
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type  string `xml:"t,attr"`
+				Value string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 3)
+	require.Len(t, sheet.Rows[0].Cells, 2)
+	require.Equal(t, "id", sheet.Rows[0].Cells[0].Value)
+	require.Equal(t, "inlineStr", sheet.Rows[0].Cells[0].Type)
+	require.Equal(t, "<Anna's> & �Mac", sheet.Rows[1].Cells[1].Value)
+	require.Empty(t, sheet.Rows[2].Cells)
+}
+
+func TestSanitizeSheetName(t *testing.T) {
+	require.Equal(t, "Sheet1", sanitizeSheetName(""))
+	require.Equal(t, "Sheet1", sanitizeSheetName("[]"))
+	require.Equal(t, "Software", sanitizeSheetName("'Software'"))
+	require.Equal(t, "abcdefghijklmnopqrstuvwxyz01234", sanitizeSheetName("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
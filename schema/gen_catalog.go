@@ -0,0 +1,274 @@
+//go:build ignore
+
+// Command gen_catalog generates the schema catalog (catalog.json) served by the
+// Fleet server, i.e. the osquery tables of the merged schema
+// (osquery_fleet_schema.json) and the extension tables registered by fleetd in
+// orbit/pkg/table. The fleetd tables that are not in the merged schema yet are
+// read from their YAML file in tables/.
+//
+// Usage: go run gen_catalog.go [output]
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/schema"
+	"gopkg.in/yaml.v2"
+)
+
+const orbitTablesDir = "../orbit/pkg/table"
+
+// allPlatforms are the platforms of the fleetd tables registered without
+// platform restrictions.
+var allPlatforms = []string{"darwin", "linux", "windows"}
+
+// pluginTableName matches the comment of the PluginTable registrations that
+// names the table of the plugin.
+var pluginTableName = regexp.MustCompile(`table name is "([a-z0-9_]+)"`)
+
+func main() {
+	output := "catalog.json"
+	if len(os.Args) > 1 {
+		output = os.Args[1]
+	}
+	if err := generate(output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(output string) error {
+	b, err := os.ReadFile("osquery_fleet_schema.json")
+	if err != nil {
+		return err
+	}
+	var merged []schema.Table
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return fmt.Errorf("unmarshal osquery fleet schema: %w", err)
+	}
+	tables := make(map[string]schema.Table, len(merged))
+	for _, t := range merged {
+		tables[t.Name] = t
+	}
+
+	registered, err := fleetdTables()
+	if err != nil {
+		return err
+	}
+	for name, platforms := range registered {
+		t, ok := tables[name]
+		if !ok {
+			if t, err = readTableYAML(name); err != nil {
+				return err
+			}
+			if len(t.Platforms) == 0 {
+				t.Platforms = platforms
+			}
+		}
+		t.Fleetd = true
+		tables[name] = t
+	}
+
+	catalog := make([]schema.Table, 0, len(tables))
+	for _, t := range tables {
+		t.Platforms = normalizePlatforms(t.Platforms)
+		for i := range t.Columns {
+			t.Columns[i].Platforms = normalizePlatforms(t.Columns[i].Platforms)
+		}
+		if t.URL == "" {
+			t.URL = "https://fleetdm.com/tables/" + t.Name
+		}
+		catalog = append(catalog, t)
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+
+	out, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, append(out, '\n'), 0o644)
+}
+
+func readTableYAML(name string) (schema.Table, error) {
+	var t schema.Table
+	b, err := os.ReadFile(filepath.Join("tables", name+".yml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, fmt.Errorf("fleetd table %s is not documented, add schema/tables/%s.yml", name, name)
+		}
+		return t, err
+	}
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("unmarshal table %s: %w", name, err)
+	}
+	if len(t.Columns) == 0 {
+		return t, fmt.Errorf("fleetd table %s has no documented columns", name)
+	}
+	// the descriptions of the YAML files may end with a new line (folded
+	// style).
+	t.Description = strings.TrimSpace(t.Description)
+	t.Examples = strings.TrimSpace(t.Examples)
+	t.Notes = strings.TrimSpace(t.Notes)
+	return t, nil
+}
+
+// fleetdTables returns the platforms of the tables registered by fleetd, by
+// name, by parsing the Table and PluginTable calls of orbit/pkg/table.
+func fleetdTables() (map[string][]string, error) {
+	files, err := filepath.Glob(filepath.Join(orbitTablesDir, "extension*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filepath.Join(orbitTablesDir, "registry.go"))
+
+	tables := make(map[string][]string)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		// the comments of the file by line, to find the names of the tables
+		// registered with PluginTable.
+		comments := make(map[int]string)
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				comments[fset.Position(c.Pos()).Line] += c.Text
+			}
+		}
+
+		var filePlatforms []string
+		for _, goos := range allPlatforms {
+			if strings.HasPrefix(filepath.Base(file), "extension_"+goos) {
+				filePlatforms = []string{goos}
+			}
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || fn.Name.Name == "Table" || fn.Name.Name == "PluginTable" {
+				continue
+			}
+			// the variables assigned to OnPlatforms options, e.g.
+			// darwin := OnPlatforms("darwin").
+			platformVars := make(map[string][]string)
+			var inspectErr error
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+						if id, ok := n.Lhs[0].(*ast.Ident); ok {
+							if platforms := onPlatforms(n.Rhs[0]); platforms != nil {
+								platformVars[id.Name] = platforms
+							}
+						}
+					}
+				case *ast.CallExpr:
+					fun, ok := n.Fun.(*ast.Ident)
+					if !ok || (fun.Name != "Table" && fun.Name != "PluginTable") || len(n.Args) == 0 {
+						return true
+					}
+
+					var name string
+					opts := n.Args[1:]
+					if fun.Name == "Table" {
+						lit, ok := n.Args[0].(*ast.BasicLit)
+						if !ok || lit.Kind != token.STRING {
+							// tables provided at runtime, e.g. by the extensions of
+							// the Runner.
+							return true
+						}
+						name, _ = strconv.Unquote(lit.Value)
+						if len(n.Args) > 3 {
+							opts = n.Args[3:]
+						} else {
+							opts = nil
+						}
+					} else {
+						m := pluginTableName.FindStringSubmatch(comments[fset.Position(n.End()).Line])
+						if m == nil {
+							inspectErr = fmt.Errorf(`%s: add a // table name is "..." comment to the PluginTable registration`, fset.Position(n.Pos()))
+							return false
+						}
+						name = m[1]
+					}
+
+					platforms := filePlatforms
+					for _, opt := range opts {
+						if p := onPlatforms(opt); p != nil {
+							platforms = p
+						} else if id, ok := opt.(*ast.Ident); ok && platformVars[id.Name] != nil {
+							platforms = platformVars[id.Name]
+						}
+					}
+					if platforms == nil {
+						platforms = allPlatforms
+					}
+					for _, p := range platforms {
+						if !contains(tables[name], p) {
+							tables[name] = append(tables[name], p)
+						}
+					}
+				}
+				return true
+			})
+			if inspectErr != nil {
+				return nil, inspectErr
+			}
+		}
+	}
+	return tables, nil
+}
+
+// onPlatforms returns the platforms of an OnPlatforms call, nil if the
+// expression is not an OnPlatforms call.
+func onPlatforms(expr ast.Expr) []string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "OnPlatforms" {
+		return nil
+	}
+	platforms := []string{}
+	for _, arg := range call.Args {
+		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			p, _ := strconv.Unquote(lit.Value)
+			platforms = append(platforms, p)
+		}
+	}
+	return platforms
+}
+
+func normalizePlatforms(platforms []string) []string {
+	var res []string
+	for _, p := range platforms {
+		if p = schema.NormalizePlatform(p); !contains(res, p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
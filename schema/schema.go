@@ -0,0 +1,131 @@
+// Package schema provides the schema of the osquery tables available on the
+// hosts enrolled in Fleet, i.e. the osquery core tables merged with Fleet's
+// overrides and the tables of fleetd (see README.md).
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// catalog is the schema catalog generated from the merged schema
+// (osquery_fleet_schema.json) and the tables registered by fleetd, see
+// gen_catalog.go.
+//
+//go:generate go run gen_catalog.go
+//go:embed catalog.json
+var catalog []byte
+
+// Table is an osquery table.
+type Table struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	URL         string   `json:"url" yaml:"url"`
+	Platforms   []string `json:"platforms" yaml:"platforms"`
+	Evented     bool     `json:"evented" yaml:"evented"`
+	Hidden      bool     `json:"hidden" yaml:"hidden"`
+	Notes       string   `json:"notes" yaml:"notes"`
+	Examples    string   `json:"examples" yaml:"examples"`
+	// Fleetd is true if the table is an extension table of fleetd.
+	Fleetd  bool     `json:"fleetd" yaml:"-"`
+	Columns []Column `json:"columns" yaml:"columns"`
+}
+
+// Column is a column of an osquery table.
+type Column struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Type        string `json:"type" yaml:"type"`
+	Required    bool   `json:"required" yaml:"required"`
+	Hidden      bool   `json:"hidden" yaml:"hidden"`
+	// Platforms are the platforms that support the column, empty if it is
+	// supported on all the platforms of its table.
+	Platforms []string `json:"platforms,omitempty" yaml:"platforms"`
+}
+
+// IsCoreOsquery returns false if the table is not a core osquery table, i.e.
+// if it is provided by fleetd or by the fleetd Chrome extension.
+func (t Table) IsCoreOsquery() bool {
+	return !t.Fleetd && !strings.Contains(t.Notes, "not a core osquery table")
+}
+
+// SupportsPlatform returns true if the table is available on the platform
+// (one of "darwin", "windows", "linux" and "chrome").
+func (t Table) SupportsPlatform(platform string) bool {
+	for _, p := range t.Platforms {
+		if NormalizePlatform(p) == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsPlatform returns true if the column is available on the platform
+// (one of "darwin", "windows", "linux" and "chrome").
+func (c Column) SupportsPlatform(platform string) bool {
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	for _, p := range c.Platforms {
+		if NormalizePlatform(p) == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePlatform returns the platform name used by Fleet for the platform
+// names used in the schema (e.g. "macOS" is "darwin").
+func NormalizePlatform(platform string) string {
+	switch strings.ToLower(platform) {
+	case "macos", "darwin":
+		return "darwin"
+	case "windows", "win32", "cygwin":
+		return "windows"
+	case "chrome", "chromeos":
+		return "chrome"
+	default:
+		return strings.ToLower(platform)
+	}
+}
+
+var (
+	loadOnce    sync.Once
+	tables      map[string]Table
+	tableList   []Table
+	errLoadOnce error
+)
+
+func load() error {
+	loadOnce.Do(func() {
+		if errLoadOnce = json.Unmarshal(catalog, &tableList); errLoadOnce != nil {
+			errLoadOnce = fmt.Errorf("unmarshal schema catalog: %w", errLoadOnce)
+			return
+		}
+		tables = make(map[string]Table, len(tableList))
+		for _, t := range tableList {
+			tables[t.Name] = t
+		}
+	})
+	return errLoadOnce
+}
+
+// Tables returns the osquery tables by name.
+func Tables() (map[string]Table, error) {
+	if err := load(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Catalog returns the osquery tables ordered by name. The returned slice must
+// not be modified.
+func Catalog() ([]Table, error) {
+	if err := load(); err != nil {
+		return nil, err
+	}
+	return tableList, nil
+}
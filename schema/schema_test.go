@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogUpToDate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates the catalog")
+	}
+
+	output := filepath.Join(t.TempDir(), "catalog.json")
+	cmd := exec.Command("go", "run", "gen_catalog.go", output)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	generated, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, string(generated), string(catalog), "schema catalog is out of date, run go generate ./schema")
+}
+
+func TestTables(t *testing.T) {
+	tables, err := Tables()
+	require.NoError(t, err)
+
+	users, ok := tables["users"]
+	require.True(t, ok)
+	require.False(t, users.Fleetd)
+	require.True(t, users.IsCoreOsquery())
+	require.True(t, users.SupportsPlatform("darwin"))
+	require.NotEmpty(t, users.Columns)
+
+	// fleetd tables are in the catalog with the platforms they are registered on
+	smart, ok := tables["smart_disk_health"]
+	require.True(t, ok)
+	require.True(t, smart.Fleetd)
+	require.False(t, smart.IsCoreOsquery())
+	require.Equal(t, []string{"darwin", "windows", "linux"}, smart.Platforms)
+	require.False(t, smart.SupportsPlatform("chrome"))
+
+	catalog, err := Catalog()
+	require.NoError(t, err)
+	require.Len(t, catalog, len(tables))
+	for i := 1; i < len(catalog); i++ {
+		require.Less(t, catalog[i-1].Name, catalog[i].Name)
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	for in, want := range map[string]string{
+		"macOS":    "darwin",
+		"darwin":   "darwin",
+		"win32":    "windows",
+		"Windows":  "windows",
+		"linux":    "linux",
+		"chromeos": "chrome",
+		"chrome":   "chrome",
+	} {
+		require.Equal(t, want, NormalizePlatform(in), in)
+	}
+}
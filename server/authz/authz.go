@@ -35,7 +35,7 @@ var policy string
 func NewAuthorizer() (*Authorizer, error) {
 	ctx := context.Background()
 	query, err := rego.New(
-		rego.Query("allowed = data.authz.allow"),
+		rego.Query("allowed = data.authz.authorized"),
 		rego.Module("policy.rego", policy),
 	).PrepareForEval(ctx)
 	if err != nil {
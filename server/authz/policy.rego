@@ -39,6 +39,117 @@ gitops := "gitops"
 # Default deny
 default allow = false
 
+# A request is authorized if a rule allows it and it doesn't cross a tenant
+# boundary.
+default authorized = false
+
+authorized {
+  allow
+  not tenant_denied
+}
+
+##
+# Tenants
+##
+
+# The users of a tenant only have access to the hosts and teams of their
+# tenant, whatever their role. Lists are filtered by the datastore instead.
+tenant_list_action {
+  action == [list, selective_list][_]
+}
+
+# tenant_team is true if the team is one of the subject's tenant.
+tenant_team(team_id) {
+  team_id == subject.tenant_team_ids[_]
+}
+
+# Hosts loaded from the datastore must be on a team of the tenant.
+tenant_denied {
+  subject.tenant_id
+  not tenant_list_action
+  object.type == "host"
+  object.id != 0
+  not tenant_team(object.team_id)
+}
+
+# Teams must belong to the tenant.
+tenant_denied {
+  subject.tenant_id
+  not tenant_list_action
+  object.type == "team"
+  object.id != 0
+  not tenant_team(object.id)
+}
+
+# Team-scoped objects must be on a team of the tenant.
+tenant_denied {
+  subject.tenant_id
+  not tenant_list_action
+  object.type != "team"
+  is_number(object.team_id)
+  object.team_id != 0
+  not tenant_team(object.team_id)
+}
+
+# The global config, the global objects and the hosts with no team are shared
+# by all tenants, their users cannot change them.
+tenant_denied {
+  subject.tenant_id
+  object.type == "app_config"
+  action == write
+}
+
+tenant_denied {
+  subject.tenant_id
+  action == write
+  is_null(object.team_id)
+}
+
+tenant_denied {
+  subject.tenant_id
+  object.type == "enroll_secret"
+  object.is_global_secret
+  action == write
+}
+
+# The hosts with no team are outside any tenant.
+tenant_denied {
+  subject.tenant_id
+  object.type == [
+    "host_script_result",
+    "host_log_collection",
+    "host_file_retrieval",
+    "host_pprof_capture",
+    "host_remote_query",
+  ][_]
+  is_null(object.team_id)
+}
+
+# Users must belong to the tenant.
+tenant_denied {
+  subject.tenant_id
+  not tenant_list_action
+  object.type == "user"
+  object.id != 0
+  not object.tenant_id == subject.tenant_id
+}
+
+# Users can only be given roles on the teams of the tenant.
+tenant_denied {
+  subject.tenant_id
+  object.type == ["user", "invite"][_]
+  team := object.teams[_]
+  not tenant_team(team.id)
+}
+
+# Only the global admins outside any tenant can read and manage the tenants.
+allow {
+  object.type == "tenant"
+  subject.global_role == admin
+  not subject.tenant_id
+  action == [read, write][_]
+}
+
 # team_role gets the role that the subject has for the team, returning undefined
 # if the user has no explicit role for that team.
 team_role(subject, team_id) = role {
@@ -185,6 +296,64 @@ allow {
 	action == [read, write][_]
 }
 
+##
+# Data exports
+##
+
+# Any user can read own data exports
+allow {
+  object.type == "data_export"
+  object.user_id == subject.id
+  action == read
+}
+
+##
+# Admin notifications
+##
+
+# Global admins can read/write (mark as read and dismiss) admin notifications.
+allow {
+  object.type == "admin_notification"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+##
+# API tokens
+##
+
+# Global admins can read/write all API tokens.
+allow {
+  object.type == "api_token"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+# Team admins can read/write the API tokens of their teams.
+allow {
+  object.type == "api_token"
+  team_role(subject, object.team_id) == admin
+  action == [read, write][_]
+}
+
+##
+# Secret variables
+##
+
+# Global admins and maintainers can read/write secret variables.
+allow {
+  object.type == "secret_variable"
+  subject.global_role == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+# Global gitops can write secret variables.
+allow {
+  object.type == "secret_variable"
+  subject.global_role == gitops
+  action == write
+}
+
 ##
 # Enroll Secrets
 ##
@@ -351,6 +520,32 @@ allow {
   action == write
 }
 
+##
+# Host groups
+##
+
+# Global admins, maintainers, observer_plus and observers can read host groups.
+allow {
+  object.type == "host_group"
+  subject.global_role == [admin, maintainer, observer_plus, observer][_]
+  action == read
+}
+
+# Team admins, maintainers, observer_plus and observers can read host groups.
+allow {
+  object.type == "host_group"
+  # If role is admin, maintainer, observer_plus or observer on any team.
+  team_role(subject, subject.teams[_].id) == [admin, maintainer, observer_plus, observer][_]
+  action == read
+}
+
+# Only global admins and maintainers can write host groups.
+allow {
+  object.type == "host_group"
+  subject.global_role == [admin, maintainer][_]
+  action == write
+}
+
 ##
 # Queries
 ##
@@ -844,6 +1039,17 @@ allow {
   action == [read, write][_]
 }
 
+##
+# Usage analytics
+##
+
+# Global admins can read the detailed usage analytics.
+allow {
+  object.type == "usage_analytics"
+  subject.global_role == admin
+  action == read
+}
+
 ##
 # Version
 ##
@@ -855,6 +1061,17 @@ allow {
   action == read
 }
 
+##
+# Osquery schema
+##
+
+# Any logged in user can read the osquery schema catalog
+allow {
+  object.type == "osquery_schema"
+  not is_null(subject)
+  action == read
+}
+
 ##
 # Host Script Result (script execution and output)
 ##
@@ -914,6 +1131,88 @@ allow {
   action == read
 }
 
+##
+# Host log collections
+##
+
+# Global admins and maintainers can request (write) and read log collections.
+allow {
+  object.type == "host_log_collection"
+  subject.global_role == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+# Team admins and maintainers can request (write) and read log collections for
+# hosts of their teams.
+allow {
+  object.type == "host_log_collection"
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+##
+# Host file retrievals
+##
+
+# Retrieving files gives access to arbitrary data on hosts, so only global
+# admins can request (write) and read file retrievals.
+allow {
+  object.type == "host_file_retrieval"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+# Team admins can request (write) and read file retrievals for hosts of their
+# teams.
+allow {
+  object.type == "host_file_retrieval"
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == admin
+  action == [read, write][_]
+}
+
+##
+# Host pprof captures
+##
+
+# Global admins and maintainers can request (write) and read pprof captures of
+# the fleetd agent.
+allow {
+  object.type == "host_pprof_capture"
+  subject.global_role == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+# Team admins and maintainers can request (write) and read pprof captures for
+# hosts of their teams.
+allow {
+  object.type == "host_pprof_capture"
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+##
+# Host remote queries
+##
+
+# Global admins and maintainers can request (write) and read remote queries.
+allow {
+  object.type == "host_remote_query"
+  subject.global_role == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
+# Team admins and maintainers can request (write) and read remote queries for
+# hosts of their teams.
+allow {
+  object.type == "host_remote_query"
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin, maintainer][_]
+  action == [read, write][_]
+}
+
 ##
 # Scripts (saved script)
 ##
@@ -947,3 +1246,42 @@ allow {
   team_role(subject, object.team_id) == [admin, maintainer, observer_plus, observer][_]
   action == read
 }
+
+# Team admins, maintainers, observer_plus and observers can read the scripts
+# shared with all teams.
+allow {
+  object.type == "script"
+  object.shared_with_all_teams == true
+  team_role(subject, subject.teams[_].id) == [admin, maintainer, observer_plus, observer][_]
+  action == read
+}
+
+##
+# Shared library (scripts and configuration profiles shared with all teams)
+##
+
+# Global admins can share (write) scripts and profiles with all teams.
+allow {
+  object.type == "shared_library"
+  is_null(object.team_id)
+  subject.global_role == admin
+  action == write
+}
+
+# Global admins, maintainers and gitops can read and write the opt-outs of the
+# teams from the shared library.
+allow {
+  object.type == "shared_library"
+  not is_null(object.team_id)
+  subject.global_role == [admin, maintainer, gitops][_]
+  action == [read, write][_]
+}
+
+# Team admins, maintainers and gitops can read and write the opt-outs of their
+# teams from the shared library.
+allow {
+  object.type == "shared_library"
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin, maintainer, gitops][_]
+  action == [read, write][_]
+}
@@ -120,6 +120,174 @@ func TestAuthorizeSession(t *testing.T) {
 	})
 }
 
+func TestAuthorizeDataExport(t *testing.T) {
+	t.Parallel()
+
+	export := &fleet.DataExport{UserID: 42}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: export, action: read, allow: false},
+
+		// Only the user that requested the export can read it
+		{user: test.UserAdmin, object: export, action: read, allow: false},
+		{user: test.UserAdmin, object: &fleet.DataExport{UserID: test.UserAdmin.ID}, action: read, allow: true},
+		{user: test.UserAdmin, object: &fleet.DataExport{UserID: test.UserAdmin.ID}, action: write, allow: false},
+		{user: test.UserMaintainer, object: export, action: read, allow: false},
+		{user: test.UserMaintainer, object: &fleet.DataExport{UserID: test.UserMaintainer.ID}, action: read, allow: true},
+		{user: test.UserObserver, object: export, action: read, allow: false},
+		{user: test.UserObserver, object: &fleet.DataExport{UserID: test.UserObserver.ID}, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: export, action: read, allow: false},
+		{user: test.UserTeamObserverTeam1, object: &fleet.DataExport{UserID: test.UserTeamObserverTeam1.ID}, action: read, allow: true},
+	})
+}
+
+func TestAuthorizeAdminNotification(t *testing.T) {
+	t.Parallel()
+
+	notification := &fleet.AdminNotification{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: notification, action: read, allow: false},
+		{user: nil, object: notification, action: write, allow: false},
+
+		{user: test.UserAdmin, object: notification, action: read, allow: true},
+		{user: test.UserAdmin, object: notification, action: write, allow: true},
+		{user: test.UserMaintainer, object: notification, action: read, allow: false},
+		{user: test.UserMaintainer, object: notification, action: write, allow: false},
+		{user: test.UserObserver, object: notification, action: read, allow: false},
+		{user: test.UserObserverPlus, object: notification, action: read, allow: false},
+		{user: test.UserGitOps, object: notification, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: notification, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: notification, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeUsageAnalytics(t *testing.T) {
+	t.Parallel()
+
+	analytics := &fleet.UsageAnalytics{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: analytics, action: read, allow: false},
+
+		{user: test.UserAdmin, object: analytics, action: read, allow: true},
+		{user: test.UserAdmin, object: analytics, action: write, allow: false},
+		{user: test.UserMaintainer, object: analytics, action: read, allow: false},
+		{user: test.UserObserver, object: analytics, action: read, allow: false},
+		{user: test.UserObserverPlus, object: analytics, action: read, allow: false},
+		{user: test.UserGitOps, object: analytics, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: analytics, action: read, allow: false},
+	})
+}
+
+func TestAuthorizeAPIToken(t *testing.T) {
+	t.Parallel()
+
+	teamAdmin := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin},
+		},
+	}
+	teamMaintainer := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer},
+		},
+	}
+	otherTeamAdmin := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin},
+		},
+	}
+	teamToken := &fleet.APIToken{TeamID: 1}
+	anyToken := &fleet.APIToken{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: teamToken, action: read, allow: false},
+		{user: nil, object: teamToken, action: write, allow: false},
+
+		{user: test.UserAdmin, object: teamToken, action: read, allow: true},
+		{user: test.UserAdmin, object: teamToken, action: write, allow: true},
+		{user: test.UserAdmin, object: anyToken, action: read, allow: true},
+
+		{user: test.UserMaintainer, object: teamToken, action: read, allow: false},
+		{user: test.UserMaintainer, object: teamToken, action: write, allow: false},
+		{user: test.UserObserver, object: teamToken, action: read, allow: false},
+		{user: test.UserGitOps, object: teamToken, action: write, allow: false},
+		{user: test.UserNoRoles, object: teamToken, action: read, allow: false},
+
+		{user: teamAdmin, object: teamToken, action: read, allow: true},
+		{user: teamAdmin, object: teamToken, action: write, allow: true},
+		{user: teamAdmin, object: anyToken, action: read, allow: false},
+		{user: teamMaintainer, object: teamToken, action: read, allow: false},
+		{user: teamMaintainer, object: teamToken, action: write, allow: false},
+		{user: otherTeamAdmin, object: teamToken, action: read, allow: false},
+		{user: otherTeamAdmin, object: teamToken, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeSecretVariable(t *testing.T) {
+	t.Parallel()
+
+	teamAdmin := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin},
+		},
+	}
+	secret := &fleet.SecretVariable{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: secret, action: read, allow: false},
+		{user: nil, object: secret, action: write, allow: false},
+		{user: test.UserNoRoles, object: secret, action: read, allow: false},
+		{user: test.UserNoRoles, object: secret, action: write, allow: false},
+
+		{user: test.UserAdmin, object: secret, action: read, allow: true},
+		{user: test.UserAdmin, object: secret, action: write, allow: true},
+		{user: test.UserMaintainer, object: secret, action: read, allow: true},
+		{user: test.UserMaintainer, object: secret, action: write, allow: true},
+		{user: test.UserGitOps, object: secret, action: read, allow: false},
+		{user: test.UserGitOps, object: secret, action: write, allow: true},
+		{user: test.UserObserver, object: secret, action: read, allow: false},
+		{user: test.UserObserverPlus, object: secret, action: read, allow: false},
+
+		{user: teamAdmin, object: secret, action: read, allow: false},
+		{user: teamAdmin, object: secret, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeHostGroup(t *testing.T) {
+	t.Parallel()
+
+	teamObserver := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver},
+		},
+	}
+	teamAdmin := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin},
+		},
+	}
+	group := &fleet.HostGroup{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: group, action: read, allow: false},
+		{user: nil, object: group, action: write, allow: false},
+		{user: test.UserNoRoles, object: group, action: read, allow: false},
+		{user: test.UserNoRoles, object: group, action: write, allow: false},
+
+		{user: test.UserAdmin, object: group, action: read, allow: true},
+		{user: test.UserAdmin, object: group, action: write, allow: true},
+		{user: test.UserMaintainer, object: group, action: read, allow: true},
+		{user: test.UserMaintainer, object: group, action: write, allow: true},
+		{user: test.UserObserver, object: group, action: read, allow: true},
+		{user: test.UserObserver, object: group, action: write, allow: false},
+		{user: test.UserObserverPlus, object: group, action: read, allow: true},
+		{user: test.UserObserverPlus, object: group, action: write, allow: false},
+		{user: test.UserGitOps, object: group, action: read, allow: false},
+		{user: test.UserGitOps, object: group, action: write, allow: false},
+
+		{user: teamObserver, object: group, action: read, allow: true},
+		{user: teamObserver, object: group, action: write, allow: false},
+		{user: teamAdmin, object: group, action: read, allow: true},
+		{user: teamAdmin, object: group, action: write, allow: false},
+	})
+}
+
 func TestAuthorizeActivity(t *testing.T) {
 	t.Parallel()
 
@@ -143,6 +311,25 @@ func TestAuthorizeActivity(t *testing.T) {
 	})
 }
 
+func TestAuthorizeOsquerySchema(t *testing.T) {
+	t.Parallel()
+
+	osquerySchema := &fleet.OsquerySchema{}
+
+	runTestCases(t, []authTestCase{
+		// Any logged in user can read the osquery schema.
+		{user: nil, object: osquerySchema, action: read, allow: false},
+		{user: test.UserNoRoles, object: osquerySchema, action: read, allow: true},
+		{user: test.UserAdmin, object: osquerySchema, action: read, allow: true},
+		{user: test.UserObserver, object: osquerySchema, action: read, allow: true},
+		{user: test.UserGitOps, object: osquerySchema, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: osquerySchema, action: read, allow: true},
+		{user: test.UserTeamGitOpsTeam1, object: osquerySchema, action: read, allow: true},
+
+		{user: test.UserAdmin, object: osquerySchema, action: write, allow: false},
+	})
+}
+
 func TestAuthorizeUser(t *testing.T) {
 	t.Parallel()
 
@@ -2016,6 +2203,136 @@ func TestAuthorizeHostScriptResult(t *testing.T) {
 	})
 }
 
+func TestAuthorizeHostLogCollection(t *testing.T) {
+	t.Parallel()
+
+	noTeam := &fleet.HostLogCollection{}
+	team1 := &fleet.HostLogCollection{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: noTeam, action: read, allow: false},
+		{user: nil, object: noTeam, action: write, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: write, allow: false},
+
+		{user: test.UserAdmin, object: noTeam, action: read, allow: true},
+		{user: test.UserAdmin, object: noTeam, action: write, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: noTeam, action: read, allow: true},
+		{user: test.UserMaintainer, object: team1, action: write, allow: true},
+		{user: test.UserObserver, object: noTeam, action: read, allow: false},
+		{user: test.UserObserverPlus, object: noTeam, action: write, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: read, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: noTeam, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: false},
+		{user: test.UserTeamObserverPlusTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeHostFileRetrieval(t *testing.T) {
+	t.Parallel()
+
+	noTeam := &fleet.HostFileRetrieval{}
+	team1 := &fleet.HostFileRetrieval{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: noTeam, action: read, allow: false},
+		{user: nil, object: noTeam, action: write, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: write, allow: false},
+
+		{user: test.UserAdmin, object: noTeam, action: read, allow: true},
+		{user: test.UserAdmin, object: noTeam, action: write, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: noTeam, action: read, allow: false},
+		{user: test.UserMaintainer, object: team1, action: write, allow: false},
+		{user: test.UserObserver, object: noTeam, action: read, allow: false},
+		{user: test.UserObserverPlus, object: noTeam, action: write, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: read, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: noTeam, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: read, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: false},
+		{user: test.UserTeamObserverPlusTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeHostPprofCapture(t *testing.T) {
+	t.Parallel()
+
+	noTeam := &fleet.HostPprofCapture{}
+	team1 := &fleet.HostPprofCapture{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: noTeam, action: read, allow: false},
+		{user: nil, object: noTeam, action: write, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: write, allow: false},
+
+		{user: test.UserAdmin, object: noTeam, action: read, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: noTeam, action: read, allow: true},
+		{user: test.UserMaintainer, object: team1, action: write, allow: true},
+		{user: test.UserObserver, object: noTeam, action: read, allow: false},
+		{user: test.UserObserverPlus, object: noTeam, action: write, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: read, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: noTeam, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: false},
+		{user: test.UserTeamObserverPlusTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamMaintainerTeam2, object: team1, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeHostRemoteQuery(t *testing.T) {
+	t.Parallel()
+
+	noTeam := &fleet.HostRemoteQuery{}
+	team1 := &fleet.HostRemoteQuery{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: noTeam, action: read, allow: false},
+		{user: nil, object: noTeam, action: write, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: write, allow: false},
+
+		{user: test.UserAdmin, object: noTeam, action: read, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: noTeam, action: read, allow: true},
+		{user: test.UserMaintainer, object: team1, action: write, allow: true},
+		{user: test.UserObserver, object: noTeam, action: read, allow: false},
+		{user: test.UserObserverPlus, object: noTeam, action: write, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: read, allow: false},
+		{user: test.UserGitOps, object: noTeam, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: noTeam, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: false},
+		{user: test.UserTeamObserverPlusTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamMaintainerTeam2, object: team1, action: write, allow: false},
+	})
+}
+
 func TestAuthorizeScript(t *testing.T) {
 	t.Parallel()
 
@@ -2106,6 +2423,108 @@ func TestAuthorizeScript(t *testing.T) {
 	})
 }
 
+func TestAuthorizeTenant(t *testing.T) {
+	t.Parallel()
+
+	// the tenant has teams 1 and 3, team 2 and the hosts with no team are
+	// outside the tenant
+	tenantAdmin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1, 3}}
+	tenantObserver := &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1, 3}}
+	tenantTeamAdmin := &fleet.User{
+		TenantID:      ptr.Uint(1),
+		TenantTeamIDs: []uint{1, 3},
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin},
+			{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin},
+		},
+	}
+	emptyTenantAdmin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(2)}
+
+	hostTeam1 := &fleet.Host{ID: 1, TeamID: ptr.Uint(1)}
+	hostTeam2 := &fleet.Host{ID: 2, TeamID: ptr.Uint(2)}
+	hostNoTeam := &fleet.Host{ID: 3}
+	config := &fleet.AppConfig{}
+
+	runTestCases(t, []authTestCase{
+		{user: tenantAdmin, object: hostTeam1, action: read, allow: true},
+		{user: tenantAdmin, object: hostTeam1, action: write, allow: true},
+		{user: tenantAdmin, object: hostTeam2, action: read, allow: false},
+		{user: tenantAdmin, object: hostTeam2, action: write, allow: false},
+		{user: tenantAdmin, object: hostNoTeam, action: read, allow: false},
+		{user: tenantAdmin, object: hostNoTeam, action: write, allow: false},
+		{user: tenantObserver, object: hostTeam1, action: read, allow: true},
+		{user: tenantObserver, object: hostTeam2, action: read, allow: false},
+		{user: tenantTeamAdmin, object: hostTeam1, action: read, allow: true},
+		{user: tenantTeamAdmin, object: hostTeam2, action: read, allow: false},
+		{user: emptyTenantAdmin, object: hostTeam1, action: read, allow: false},
+
+		// hosts built from a team ID
+		{user: tenantAdmin, object: &fleet.Host{TeamID: ptr.Uint(3)}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Host{TeamID: ptr.Uint(2)}, action: write, allow: false},
+
+		// lists are filtered by the datastore, the empty team is used to list
+		// teams
+		{user: tenantAdmin, object: &fleet.Host{}, action: list, allow: true},
+		{user: tenantAdmin, object: &fleet.Host{TeamID: ptr.Uint(2)}, action: list, allow: true},
+		{user: tenantAdmin, object: &fleet.Team{}, action: read, allow: true},
+
+		{user: tenantAdmin, object: &fleet.Team{ID: 1}, action: read, allow: true},
+		{user: tenantAdmin, object: &fleet.Team{ID: 3}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Team{ID: 2}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.Team{ID: 2}, action: write, allow: false},
+		{user: tenantTeamAdmin, object: &fleet.Team{ID: 1}, action: write, allow: true},
+		{user: tenantTeamAdmin, object: &fleet.Team{ID: 2}, action: write, allow: false},
+
+		// other team-scoped objects
+		{user: tenantAdmin, object: &fleet.Policy{PolicyData: fleet.PolicyData{TeamID: ptr.Uint(1)}}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Policy{PolicyData: fleet.PolicyData{TeamID: ptr.Uint(2)}}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.Script{TeamID: ptr.Uint(2)}, action: write, allow: false},
+
+		// global objects and hosts with no team are shared by all tenants
+		{user: tenantAdmin, object: config, action: read, allow: true},
+		{user: tenantAdmin, object: config, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.Query{}, action: read, allow: true},
+		{user: tenantAdmin, object: &fleet.Query{}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.Query{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Policy{}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.EnrollSecret{}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.EnrollSecret{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Host{}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.HostScriptResult{}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.HostScriptResult{}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.HostScriptResult{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.HostRemoteQuery{}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.HostRemoteQuery{TeamID: ptr.Uint(3)}, action: read, allow: true},
+
+		// users
+		{user: tenantAdmin, object: &fleet.User{ID: 10, TenantID: ptr.Uint(1)}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.User{ID: 10, TenantID: ptr.Uint(2)}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.User{ID: 10}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.User{ID: 10}, action: writeRole, allow: false},
+		{user: tenantAdmin, object: &fleet.User{}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleAdmin}}}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, action: write, allow: false},
+		{user: tenantAdmin, object: &fleet.Invite{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, action: write, allow: true},
+		{user: tenantAdmin, object: &fleet.Invite{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleObserver}}}, action: write, allow: false},
+
+		// only the global admins outside any tenant manage the tenants
+		{user: tenantAdmin, object: &fleet.Tenant{}, action: read, allow: false},
+		{user: tenantAdmin, object: &fleet.Tenant{ID: 1}, action: write, allow: false},
+		{user: test.UserAdmin, object: &fleet.Tenant{}, action: read, allow: true},
+		{user: test.UserAdmin, object: &fleet.Tenant{ID: 1}, action: write, allow: true},
+		{user: test.UserMaintainer, object: &fleet.Tenant{}, action: read, allow: false},
+		{user: test.UserObserver, object: &fleet.Tenant{}, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: &fleet.Tenant{}, action: read, allow: false},
+		{user: test.UserNoRoles, object: &fleet.Tenant{}, action: read, allow: false},
+
+		// users outside any tenant are not restricted
+		{user: test.UserAdmin, object: hostTeam2, action: write, allow: true},
+		{user: test.UserAdmin, object: hostNoTeam, action: write, allow: true},
+		{user: test.UserAdmin, object: &fleet.Team{ID: 2}, action: write, allow: true},
+		{user: test.UserAdmin, object: config, action: write, allow: true},
+	})
+}
+
 func TestJSONToInterfaceUser(t *testing.T) {
 	t.Parallel()
 
@@ -2155,3 +2574,35 @@ func TestHostHealth(t *testing.T) {
 		{user: test.UserTeamMaintainerTeam2, object: hostHealth, action: read, allow: false},
 	})
 }
+
+func TestAuthorizeSharedLibrary(t *testing.T) {
+	t.Parallel()
+
+	sharedScript := &fleet.Script{SharedWithAllTeams: true}
+	sharedLibrary := &fleet.SharedLibrary{}
+	team1OptOuts := &fleet.SharedLibrary{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: test.UserNoRoles, object: sharedScript, action: read, allow: false},
+		{user: test.UserTeamObserverTeam1, object: sharedScript, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: sharedScript, action: write, allow: false},
+		{user: test.UserTeamGitOpsTeam1, object: sharedScript, action: read, allow: false},
+		{user: test.UserTeamObserverTeam1, object: &fleet.Script{}, action: read, allow: false},
+
+		{user: test.UserNoRoles, object: sharedLibrary, action: write, allow: false},
+		{user: test.UserAdmin, object: sharedLibrary, action: write, allow: true},
+		{user: test.UserMaintainer, object: sharedLibrary, action: write, allow: false},
+		{user: test.UserGitOps, object: sharedLibrary, action: write, allow: false},
+		{user: test.UserTeamAdminTeam1, object: sharedLibrary, action: write, allow: false},
+
+		{user: test.UserNoRoles, object: team1OptOuts, action: read, allow: false},
+		{user: test.UserAdmin, object: team1OptOuts, action: write, allow: true},
+		{user: test.UserMaintainer, object: team1OptOuts, action: write, allow: true},
+		{user: test.UserGitOps, object: team1OptOuts, action: write, allow: true},
+		{user: test.UserObserver, object: team1OptOuts, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: team1OptOuts, action: write, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1OptOuts, action: read, allow: true},
+		{user: test.UserTeamGitOpsTeam1, object: team1OptOuts, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1OptOuts, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1OptOuts, action: write, allow: false},
+	})
+}
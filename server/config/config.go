@@ -51,24 +51,26 @@ type MysqlConfig struct {
 
 // RedisConfig defines configs related to Redis
 type RedisConfig struct {
-	Address                   string
-	Username                  string
-	Password                  string
-	Database                  int
-	UseTLS                    bool          `yaml:"use_tls"`
-	DuplicateResults          bool          `yaml:"duplicate_results"`
-	ConnectTimeout            time.Duration `yaml:"connect_timeout"`
-	KeepAlive                 time.Duration `yaml:"keep_alive"`
-	ConnectRetryAttempts      int           `yaml:"connect_retry_attempts"`
-	ClusterFollowRedirections bool          `yaml:"cluster_follow_redirections"`
-	ClusterReadFromReplica    bool          `yaml:"cluster_read_from_replica"`
-	TLSCert                   string        `yaml:"tls_cert"`
-	TLSKey                    string        `yaml:"tls_key"`
-	TLSCA                     string        `yaml:"tls_ca"`
-	TLSServerName             string        `yaml:"tls_server_name"`
-	TLSHandshakeTimeout       time.Duration `yaml:"tls_handshake_timeout"`
-	MaxIdleConns              int           `yaml:"max_idle_conns"`
-	MaxOpenConns              int           `yaml:"max_open_conns"`
+	Address                    string
+	Username                   string
+	Password                   string
+	Database                   int
+	UseTLS                     bool          `yaml:"use_tls"`
+	DuplicateResults           bool          `yaml:"duplicate_results"`
+	LiveQueryResultsBufferSize int           `yaml:"live_query_results_buffer_size"`
+	LiveQueryResultsBufferTTL  time.Duration `yaml:"live_query_results_buffer_ttl"`
+	ConnectTimeout             time.Duration `yaml:"connect_timeout"`
+	KeepAlive                  time.Duration `yaml:"keep_alive"`
+	ConnectRetryAttempts       int           `yaml:"connect_retry_attempts"`
+	ClusterFollowRedirections  bool          `yaml:"cluster_follow_redirections"`
+	ClusterReadFromReplica     bool          `yaml:"cluster_read_from_replica"`
+	TLSCert                    string        `yaml:"tls_cert"`
+	TLSKey                     string        `yaml:"tls_key"`
+	TLSCA                      string        `yaml:"tls_ca"`
+	TLSServerName              string        `yaml:"tls_server_name"`
+	TLSHandshakeTimeout        time.Duration `yaml:"tls_handshake_timeout"`
+	MaxIdleConns               int           `yaml:"max_idle_conns"`
+	MaxOpenConns               int           `yaml:"max_open_conns"`
 	// this config is an int on MysqlConfig, but it should be a time.Duration.
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 	IdleTimeout     time.Duration `yaml:"idle_timeout"`
@@ -94,7 +96,22 @@ type ServerConfig struct {
 	Keepalive                   bool   `yaml:"keepalive"`
 	SandboxEnabled              bool   `yaml:"sandbox_enabled"`
 	WebsocketsAllowUnsafeOrigin bool   `yaml:"websockets_allow_unsafe_origin"`
+	WebsocketsCompression       bool   `yaml:"websockets_compression"`
 	FrequentCleanupsEnabled     bool   `yaml:"frequent_cleanups_enabled"`
+	PrivateKey                  string `yaml:"private_key"`
+	LatencyBudget               string `yaml:"latency_budget"` // duration or per API path
+	LatencyBudgetExplain        bool   `yaml:"latency_budget_explain"`
+	DatabaseOptimizeTables      bool   `yaml:"database_optimize_tables"`
+	ClientCertificateEnrollment bool   `yaml:"client_certificate_enrollment"`
+	ClientCA                    string `yaml:"client_ca"`
+}
+
+// LatencyBudgetForPath returns the latency budget of the API path (e.g.
+// "/api/_version_/fleet/hosts/{id}"), 0 if the requests to that path are not
+// tracked. The budget of the "*" path applies to the paths without a budget.
+func (s ServerConfig) LatencyBudgetForPath(path string) time.Duration {
+	def := configForKeyOrDuration("server.latency_budget", "*", s.LatencyBudget, 0)
+	return configForKeyOrDuration("server.latency_budget", path, s.LatencyBudget, def)
 }
 
 func (s *ServerConfig) DefaultHTTPServer(ctx context.Context, handler http.Handler) *http.Server {
@@ -457,6 +474,9 @@ type MDMConfig struct {
 	// AppleDEPSyncPeriodicity is the duration between DEP device syncing
 	// (fetching and setting of DEP profiles).
 	AppleDEPSyncPeriodicity time.Duration `yaml:"apple_dep_sync_periodicity"`
+	// ApplePushCoalesceWindow is the duration over which the APNs push
+	// notifications are batched before being sent, 0 sends them immediately.
+	ApplePushCoalesceWindow time.Duration `yaml:"apple_push_coalesce_window"`
 	// AppleSCEPChallenge is the SCEP challenge for SCEP enrollment requests.
 	AppleSCEPChallenge string `yaml:"apple_scep_challenge"`
 	// AppleSCEPSignerValidityDays are the days signed client certificates will
@@ -465,6 +485,14 @@ type MDMConfig struct {
 	// AppleSCEPSignerAllowRenewalDays are the allowable renewal days for
 	// certificates.
 	AppleSCEPSignerAllowRenewalDays int `yaml:"apple_scep_signer_allow_renewal_days"`
+	// AppleProfileCertificateValidityDays are the days the certificates issued
+	// by the SCEP payloads of Wi-Fi and VPN configuration profiles are valid.
+	// It is used to re-deliver those profiles before the certificates expire.
+	AppleProfileCertificateValidityDays int `yaml:"apple_profile_certificate_validity_days"`
+	// AppleRecoveryLockRotationDays are the days after which Fleet rotates the
+	// Recovery Lock password it sets on the Apple silicon Macs, 0 to not set
+	// Recovery Lock passwords.
+	AppleRecoveryLockRotationDays int `yaml:"apple_recovery_lock_rotation_days"`
 
 	// WindowsWSTEPIdentityCert is the path to the certificate used to sign
 	// WSTEP responses.
@@ -670,6 +698,26 @@ func (m *MDMConfig) AppleBM() (tok *nanodep_client.OAuth1Tokens, err error) {
 	return m.appleBMToken, nil
 }
 
+// AppleBMAssets returns the PEM-encoded Apple BM certificate and private key,
+// and the encrypted server token, as configured. Unlike AppleBM, it does not
+// decrypt the token so that an expired token can still be backed up.
+func (m *MDMConfig) AppleBMAssets() (pemCert, pemKey, encToken []byte, err error) {
+	pair := x509KeyPairConfig{
+		m.AppleBMCert,
+		[]byte(m.AppleBMCertBytes),
+		m.AppleBMKey,
+		[]byte(m.AppleBMKeyBytes),
+	}
+	if _, err := pair.Parse(false); err != nil {
+		return nil, nil, nil, fmt.Errorf("Apple BM configuration: %w", err)
+	}
+	encToken, err = m.loadAppleBMEncryptedToken()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Apple BM configuration: %w", err)
+	}
+	return pair.certBytes, pair.keyBytes, encToken, nil
+}
+
 func (m *MDMConfig) loadAppleBMEncryptedToken() ([]byte, error) {
 	if m.AppleBMServerToken == "" && m.AppleBMServerTokenBytes == "" {
 		return nil, errors.New("no token provided")
@@ -805,6 +853,8 @@ func (man Manager) addConfigs() {
 		"Redis server database number")
 	man.addConfigBool("redis.use_tls", false, "Redis server enable TLS")
 	man.addConfigBool("redis.duplicate_results", false, "Duplicate Live Query results to another Redis channel")
+	man.addConfigInt("redis.live_query_results_buffer_size", 100000, "Maximum number of Live Query results buffered per campaign so that clients can resume reading them (0 disables the buffering)")
+	man.addConfigDuration("redis.live_query_results_buffer_ttl", 10*time.Minute, "Time for which the buffered Live Query results are kept after the last result of the campaign")
 	man.addConfigDuration("redis.connect_timeout", 5*time.Second, "Timeout at connection time")
 	man.addConfigDuration("redis.keep_alive", 10*time.Second, "Interval between keep alive probes")
 	man.addConfigInt("redis.connect_retry_attempts", 0, "Number of attempts to retry a failed connection")
@@ -842,7 +892,19 @@ func (man Manager) addConfigs() {
 	man.addConfigBool("server.sandbox_enabled", false,
 		"When enabled, Fleet limits some features for the Sandbox")
 	man.addConfigBool("server.websockets_allow_unsafe_origin", false, "Disable checking the origin header on websocket connections, this is sometimes necessary when proxies rewrite origin headers between the client and the Fleet webserver")
+	man.addConfigBool("server.websockets_compression", true, "Enable per-message compression (permessage-deflate) on websocket connections when supported by the client")
 	man.addConfigBool("server.frequent_cleanups_enabled", false, "Enable frequent cleanups of expired data (15 minute interval)")
+	man.addConfigString("server.private_key", "", "Used for encrypting sensitive data, such as the users' TOTP secrets and the secret variables. Must be at least 32 bytes long")
+	man.addConfigString("server.latency_budget", "",
+		"Latency budget of the API requests, the requests exceeding it are logged with their slowest database queries (e.g. '2s' or set per API path '/api/_version_/fleet/hosts=2s&*=5s')")
+	man.addConfigBool("server.latency_budget_explain", false,
+		"Log the execution plan of the slowest database queries of the requests exceeding their latency budget (for debugging only)")
+	man.addConfigBool("server.database_optimize_tables", false,
+		"Rebuild the database tables with a large amount of unused space monthly, in the database maintenance cron")
+	man.addConfigBool("server.client_certificate_enrollment", false,
+		"Allow osquery and orbit to enroll with a TLS client certificate instead of an enroll secret (requires server.tls)")
+	man.addConfigString("server.client_ca", "",
+		"Path to the PEM-encoded CA certificates trusted to issue the client certificates used for enrollment")
 
 	// Hide the sandbox flag as we don't want it to be discoverable for users for now
 	sandboxFlag := man.command.PersistentFlags().Lookup(flagNameFromConfigKey("server.sandbox_enabled"))
@@ -1109,8 +1171,11 @@ func (man Manager) addConfigs() {
 	man.addConfigBool("mdm.apple_enable", false, "Enable MDM Apple functionality")
 	man.addConfigInt("mdm.apple_scep_signer_validity_days", 365, "Days signed client certificates will be valid")
 	man.addConfigInt("mdm.apple_scep_signer_allow_renewal_days", 14, "Allowable renewal days for client certificates")
+	man.addConfigInt("mdm.apple_profile_certificate_validity_days", 365, "Days the SCEP certificates of Wi-Fi and VPN profiles are valid")
+	man.addConfigInt("mdm.apple_recovery_lock_rotation_days", 0, "Days after which the Recovery Lock passwords of Apple silicon Macs are rotated (0 disables Recovery Lock)")
 	man.addConfigString("mdm.apple_scep_challenge", "", "SCEP static challenge for enrollment")
 	man.addConfigDuration("mdm.apple_dep_sync_periodicity", 1*time.Minute, "How much time to wait for DEP profile assignment")
+	man.addConfigDuration("mdm.apple_push_coalesce_window", 0, "How much time to batch and deduplicate the APNs push notifications before sending them (0 sends them immediately)")
 	man.addConfigString("mdm.windows_wstep_identity_cert", "", "Microsoft WSTEP PEM-encoded certificate path")
 	man.addConfigString("mdm.windows_wstep_identity_key", "", "Microsoft WSTEP PEM-encoded private key path")
 	man.addConfigString("mdm.windows_wstep_identity_cert_bytes", "", "Microsoft WSTEP PEM-encoded certificate bytes")
@@ -1159,29 +1224,31 @@ func (man Manager) LoadConfig() FleetConfig {
 		Mysql:            loadMysqlConfig("mysql"),
 		MysqlReadReplica: loadMysqlConfig("mysql_read_replica"),
 		Redis: RedisConfig{
-			Address:                   man.getConfigString("redis.address"),
-			Username:                  man.getConfigString("redis.username"),
-			Password:                  man.getConfigString("redis.password"),
-			Database:                  man.getConfigInt("redis.database"),
-			UseTLS:                    man.getConfigBool("redis.use_tls"),
-			DuplicateResults:          man.getConfigBool("redis.duplicate_results"),
-			ConnectTimeout:            man.getConfigDuration("redis.connect_timeout"),
-			KeepAlive:                 man.getConfigDuration("redis.keep_alive"),
-			ConnectRetryAttempts:      man.getConfigInt("redis.connect_retry_attempts"),
-			ClusterFollowRedirections: man.getConfigBool("redis.cluster_follow_redirections"),
-			ClusterReadFromReplica:    man.getConfigBool("redis.cluster_read_from_replica"),
-			TLSCert:                   man.getConfigString("redis.tls_cert"),
-			TLSKey:                    man.getConfigString("redis.tls_key"),
-			TLSCA:                     man.getConfigString("redis.tls_ca"),
-			TLSServerName:             man.getConfigString("redis.tls_server_name"),
-			TLSHandshakeTimeout:       man.getConfigDuration("redis.tls_handshake_timeout"),
-			MaxIdleConns:              man.getConfigInt("redis.max_idle_conns"),
-			MaxOpenConns:              man.getConfigInt("redis.max_open_conns"),
-			ConnMaxLifetime:           man.getConfigDuration("redis.conn_max_lifetime"),
-			IdleTimeout:               man.getConfigDuration("redis.idle_timeout"),
-			ConnWaitTimeout:           man.getConfigDuration("redis.conn_wait_timeout"),
-			WriteTimeout:              man.getConfigDuration("redis.write_timeout"),
-			ReadTimeout:               man.getConfigDuration("redis.read_timeout"),
+			Address:                    man.getConfigString("redis.address"),
+			Username:                   man.getConfigString("redis.username"),
+			Password:                   man.getConfigString("redis.password"),
+			Database:                   man.getConfigInt("redis.database"),
+			UseTLS:                     man.getConfigBool("redis.use_tls"),
+			DuplicateResults:           man.getConfigBool("redis.duplicate_results"),
+			LiveQueryResultsBufferSize: man.getConfigInt("redis.live_query_results_buffer_size"),
+			LiveQueryResultsBufferTTL:  man.getConfigDuration("redis.live_query_results_buffer_ttl"),
+			ConnectTimeout:             man.getConfigDuration("redis.connect_timeout"),
+			KeepAlive:                  man.getConfigDuration("redis.keep_alive"),
+			ConnectRetryAttempts:       man.getConfigInt("redis.connect_retry_attempts"),
+			ClusterFollowRedirections:  man.getConfigBool("redis.cluster_follow_redirections"),
+			ClusterReadFromReplica:     man.getConfigBool("redis.cluster_read_from_replica"),
+			TLSCert:                    man.getConfigString("redis.tls_cert"),
+			TLSKey:                     man.getConfigString("redis.tls_key"),
+			TLSCA:                      man.getConfigString("redis.tls_ca"),
+			TLSServerName:              man.getConfigString("redis.tls_server_name"),
+			TLSHandshakeTimeout:        man.getConfigDuration("redis.tls_handshake_timeout"),
+			MaxIdleConns:               man.getConfigInt("redis.max_idle_conns"),
+			MaxOpenConns:               man.getConfigInt("redis.max_open_conns"),
+			ConnMaxLifetime:            man.getConfigDuration("redis.conn_max_lifetime"),
+			IdleTimeout:                man.getConfigDuration("redis.idle_timeout"),
+			ConnWaitTimeout:            man.getConfigDuration("redis.conn_wait_timeout"),
+			WriteTimeout:               man.getConfigDuration("redis.write_timeout"),
+			ReadTimeout:                man.getConfigDuration("redis.read_timeout"),
 		},
 		Server: ServerConfig{
 			Address:                     man.getConfigString("server.address"),
@@ -1193,7 +1260,14 @@ func (man Manager) LoadConfig() FleetConfig {
 			Keepalive:                   man.getConfigBool("server.keepalive"),
 			SandboxEnabled:              man.getConfigBool("server.sandbox_enabled"),
 			WebsocketsAllowUnsafeOrigin: man.getConfigBool("server.websockets_allow_unsafe_origin"),
+			WebsocketsCompression:       man.getConfigBool("server.websockets_compression"),
 			FrequentCleanupsEnabled:     man.getConfigBool("server.frequent_cleanups_enabled"),
+			PrivateKey:                  man.getConfigString("server.private_key"),
+			LatencyBudget:               man.getConfigString("server.latency_budget"),
+			LatencyBudgetExplain:        man.getConfigBool("server.latency_budget_explain"),
+			DatabaseOptimizeTables:      man.getConfigBool("server.database_optimize_tables"),
+			ClientCertificateEnrollment: man.getConfigBool("server.client_certificate_enrollment"),
+			ClientCA:                    man.getConfigString("server.client_ca"),
 		},
 		Auth: AuthConfig{
 			BcryptCost:  man.getConfigInt("auth.bcrypt_cost"),
@@ -1370,29 +1444,32 @@ func (man Manager) LoadConfig() FleetConfig {
 			},
 		},
 		MDM: MDMConfig{
-			AppleAPNsCert:                   man.getConfigString("mdm.apple_apns_cert"),
-			AppleAPNsCertBytes:              man.getConfigString("mdm.apple_apns_cert_bytes"),
-			AppleAPNsKey:                    man.getConfigString("mdm.apple_apns_key"),
-			AppleAPNsKeyBytes:               man.getConfigString("mdm.apple_apns_key_bytes"),
-			AppleSCEPCert:                   man.getConfigString("mdm.apple_scep_cert"),
-			AppleSCEPCertBytes:              man.getConfigString("mdm.apple_scep_cert_bytes"),
-			AppleSCEPKey:                    man.getConfigString("mdm.apple_scep_key"),
-			AppleSCEPKeyBytes:               man.getConfigString("mdm.apple_scep_key_bytes"),
-			AppleBMServerToken:              man.getConfigString("mdm.apple_bm_server_token"),
-			AppleBMServerTokenBytes:         man.getConfigString("mdm.apple_bm_server_token_bytes"),
-			AppleBMCert:                     man.getConfigString("mdm.apple_bm_cert"),
-			AppleBMCertBytes:                man.getConfigString("mdm.apple_bm_cert_bytes"),
-			AppleBMKey:                      man.getConfigString("mdm.apple_bm_key"),
-			AppleBMKeyBytes:                 man.getConfigString("mdm.apple_bm_key_bytes"),
-			AppleEnable:                     man.getConfigBool("mdm.apple_enable"),
-			AppleSCEPSignerValidityDays:     man.getConfigInt("mdm.apple_scep_signer_validity_days"),
-			AppleSCEPSignerAllowRenewalDays: man.getConfigInt("mdm.apple_scep_signer_allow_renewal_days"),
-			AppleSCEPChallenge:              man.getConfigString("mdm.apple_scep_challenge"),
-			AppleDEPSyncPeriodicity:         man.getConfigDuration("mdm.apple_dep_sync_periodicity"),
-			WindowsWSTEPIdentityCert:        man.getConfigString("mdm.windows_wstep_identity_cert"),
-			WindowsWSTEPIdentityKey:         man.getConfigString("mdm.windows_wstep_identity_key"),
-			WindowsWSTEPIdentityCertBytes:   man.getConfigString("mdm.windows_wstep_identity_cert_bytes"),
-			WindowsWSTEPIdentityKeyBytes:    man.getConfigString("mdm.windows_wstep_identity_key_bytes"),
+			AppleAPNsCert:                       man.getConfigString("mdm.apple_apns_cert"),
+			AppleAPNsCertBytes:                  man.getConfigString("mdm.apple_apns_cert_bytes"),
+			AppleAPNsKey:                        man.getConfigString("mdm.apple_apns_key"),
+			AppleAPNsKeyBytes:                   man.getConfigString("mdm.apple_apns_key_bytes"),
+			AppleSCEPCert:                       man.getConfigString("mdm.apple_scep_cert"),
+			AppleSCEPCertBytes:                  man.getConfigString("mdm.apple_scep_cert_bytes"),
+			AppleSCEPKey:                        man.getConfigString("mdm.apple_scep_key"),
+			AppleSCEPKeyBytes:                   man.getConfigString("mdm.apple_scep_key_bytes"),
+			AppleBMServerToken:                  man.getConfigString("mdm.apple_bm_server_token"),
+			AppleBMServerTokenBytes:             man.getConfigString("mdm.apple_bm_server_token_bytes"),
+			AppleBMCert:                         man.getConfigString("mdm.apple_bm_cert"),
+			AppleBMCertBytes:                    man.getConfigString("mdm.apple_bm_cert_bytes"),
+			AppleBMKey:                          man.getConfigString("mdm.apple_bm_key"),
+			AppleBMKeyBytes:                     man.getConfigString("mdm.apple_bm_key_bytes"),
+			AppleEnable:                         man.getConfigBool("mdm.apple_enable"),
+			AppleSCEPSignerValidityDays:         man.getConfigInt("mdm.apple_scep_signer_validity_days"),
+			AppleSCEPSignerAllowRenewalDays:     man.getConfigInt("mdm.apple_scep_signer_allow_renewal_days"),
+			AppleProfileCertificateValidityDays: man.getConfigInt("mdm.apple_profile_certificate_validity_days"),
+			AppleRecoveryLockRotationDays:       man.getConfigInt("mdm.apple_recovery_lock_rotation_days"),
+			AppleSCEPChallenge:                  man.getConfigString("mdm.apple_scep_challenge"),
+			AppleDEPSyncPeriodicity:             man.getConfigDuration("mdm.apple_dep_sync_periodicity"),
+			ApplePushCoalesceWindow:             man.getConfigDuration("mdm.apple_push_coalesce_window"),
+			WindowsWSTEPIdentityCert:            man.getConfigString("mdm.windows_wstep_identity_cert"),
+			WindowsWSTEPIdentityKey:             man.getConfigString("mdm.windows_wstep_identity_key"),
+			WindowsWSTEPIdentityCertBytes:       man.getConfigString("mdm.windows_wstep_identity_cert_bytes"),
+			WindowsWSTEPIdentityKeyBytes:        man.getConfigString("mdm.windows_wstep_identity_key_bytes"),
 		},
 	}
 
@@ -1400,6 +1477,14 @@ func (man Manager) LoadConfig() FleetConfig {
 	for task := range knownAsyncTasks {
 		cfg.Osquery.AsyncConfigForTask(task)
 	}
+	// and that the latency budget is valid for all the configured API paths
+	cfg.Server.LatencyBudgetForPath("*")
+	if strings.Contains(cfg.Server.LatencyBudget, "=") {
+		q, _ := url.ParseQuery(cfg.Server.LatencyBudget) // already validated above
+		for path := range q {
+			cfg.Server.LatencyBudgetForPath(path)
+		}
+	}
 
 	return cfg
 }
@@ -1672,6 +1757,9 @@ func TestConfig() FleetConfig {
 		testLogFile = "NUL"
 	}
 	return FleetConfig{
+		Server: ServerConfig{
+			PrivateKey: "ZEBZsC2bCkYgaLWwdCqBbmaYm3OKnRQS",
+		},
 		App: AppConfig{
 			TokenKeySize:              24,
 			InviteTokenValidityPeriod: 5 * 24 * time.Hour,
@@ -1745,6 +1833,7 @@ func SetTestMDMConfig(t testing.TB, cfg *FleetConfig, cert, key []byte, appleBMT
 	cfg.MDM.appleSCEPPEMKey = key
 	cfg.MDM.appleBMToken = appleBMToken
 	cfg.MDM.AppleSCEPSignerValidityDays = 365
+	cfg.MDM.AppleProfileCertificateValidityDays = 365
 	cfg.MDM.AppleSCEPChallenge = "testchallenge"
 
 	if wstepCertAndKeyDir == "" {
@@ -60,6 +60,9 @@ func TestConfigRoundtrip(t *testing.T) {
 				case "AsyncHostCollectInterval", "AsyncHostCollectLockTimeout":
 					// supports a duration or per-task config
 					key_v.SetString("30s")
+				case "LatencyBudget":
+					// supports a duration or per-path config
+					key_v.SetString("2s")
 				default:
 					key_v.SetString(v.Elem().Type().Field(conf_index).Name + "_" + conf_v.Type().Field(key_index).Name)
 				}
@@ -342,6 +345,84 @@ osquery:
 	}
 }
 
+func TestLatencyBudgetConfig(t *testing.T) {
+	const hostsPath, hostPath = "/api/_version_/fleet/hosts", "/api/_version_/fleet/hosts/{id}"
+
+	cases := []struct {
+		desc      string
+		yaml      string
+		panics    bool
+		wantHosts time.Duration
+		wantHost  time.Duration
+	}{
+		{
+			desc: "not set",
+			yaml: `
+server:
+  address: 0.0.0.0:8080`,
+		},
+		{
+			desc: "all paths",
+			yaml: `
+server:
+  latency_budget: 2s`,
+			wantHosts: 2 * time.Second,
+			wantHost:  2 * time.Second,
+		},
+		{
+			desc: "per path",
+			yaml: `
+server:
+  latency_budget: /api/_version_/fleet/hosts=3s`,
+			wantHosts: 3 * time.Second,
+		},
+		{
+			desc: "per path with default",
+			yaml: `
+server:
+  latency_budget: /api/_version_/fleet/hosts=3s&*=500ms`,
+			wantHosts: 3 * time.Second,
+			wantHost:  500 * time.Millisecond,
+		},
+		{
+			desc: "invalid duration",
+			yaml: `
+server:
+  latency_budget: 2z`,
+			panics: true,
+		},
+		{
+			desc: "invalid duration of a path",
+			yaml: `
+server:
+  latency_budget: /api/_version_/fleet/hosts=3s&/api/_version_/fleet/labels=nope`,
+			panics: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var cmd cobra.Command
+			cmd.PersistentFlags().StringP("config", "c", "", "Path to a configuration file")
+			man := NewManager(&cmd)
+
+			man.viper.SetConfigType("yaml")
+			require.NoError(t, man.viper.ReadConfig(strings.NewReader(c.yaml)))
+			os.Clearenv()
+
+			if c.panics {
+				require.Panics(t, func() { man.LoadConfig() })
+				return
+			}
+
+			var loadedCfg FleetConfig
+			require.NotPanics(t, func() { loadedCfg = man.LoadConfig() })
+			require.Equal(t, c.wantHosts, loadedCfg.Server.LatencyBudgetForPath(hostsPath))
+			require.Equal(t, c.wantHost, loadedCfg.Server.LatencyBudgetForPath(hostPath))
+		})
+	}
+}
+
 func TestToTLSConfig(t *testing.T) {
 	dir := t.TempDir()
 	caFile, certFile, keyFile, garbageFile := filepath.Join(dir, "ca"),
@@ -511,6 +592,18 @@ func TestAppleBMConfig(t *testing.T) {
 			require.Regexp(t, c.errMatches, err.Error())
 		})
 	}
+
+	// the raw assets are returned even if the token cannot be decrypted
+	mdm := MDMConfig{AppleBMCert: certFile, AppleBMKeyBytes: string(testKey), AppleBMServerToken: garbageFile}
+	cert, key, tok, err := mdm.AppleBMAssets()
+	require.NoError(t, err)
+	require.Equal(t, testCert, cert)
+	require.Equal(t, testKey, key)
+	require.Equal(t, []byte("zzzz"), tok)
+
+	mdm = MDMConfig{AppleBMCert: certFile, AppleBMKey: keyFile}
+	_, _, _, err = mdm.AppleBMAssets()
+	require.ErrorContains(t, err, "Apple BM configuration: no token provided")
 }
 
 func TestMicrosoftWSTEPConfig(t *testing.T) {
@@ -0,0 +1,27 @@
+// Package clientcert provides the verified TLS client certificate of a
+// request in its context.
+package clientcert
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+type key int
+
+const certKey key = 0
+
+// NewContext returns a new context carrying the verified client certificate.
+func NewContext(ctx context.Context, cert *x509.Certificate) context.Context {
+	return context.WithValue(ctx, certKey, cert)
+}
+
+// FromContext extracts the verified client certificate from context if
+// present.
+func FromContext(ctx context.Context) *x509.Certificate {
+	cert, ok := ctx.Value(certKey).(*x509.Certificate)
+	if !ok {
+		return nil
+	}
+	return cert
+}
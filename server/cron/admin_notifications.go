@@ -0,0 +1,23 @@
+package cron
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/schedule"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// NotifyAdminsOnJobError returns a schedule.JobErrorHandler that raises an
+// admin notification when a job of the schedule fails, so that the failure
+// is not only in the server logs.
+func NotifyAdminsOnJobError(ds fleet.Datastore, scheduleName string, logger kitlog.Logger) schedule.JobErrorHandler {
+	return func(ctx context.Context, jobID string, err error) {
+		if err := ds.NewAdminNotification(ctx, fleet.NewCronJobFailedNotification(scheduleName, jobID, err)); err != nil {
+			level.Error(logger).Log("msg", "create admin notification for failed job", "jobID", jobID, "err", err)
+			ctxerr.Handle(ctx, err)
+		}
+	}
+}
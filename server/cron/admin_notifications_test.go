@@ -0,0 +1,33 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyAdminsOnJobError(t *testing.T) {
+	ds := new(mock.Store)
+	var got *fleet.AdminNotification
+	ds.NewAdminNotificationFunc = func(ctx context.Context, notification *fleet.AdminNotification) error {
+		got = notification
+		return nil
+	}
+
+	handler := NotifyAdminsOnJobError(ds, string(fleet.CronVulnerabilities), kitlog.NewNopLogger())
+	handler(context.Background(), "cron_vulnerabilities", errors.New("failed to download the NVD feed"))
+	require.True(t, ds.NewAdminNotificationFuncInvoked)
+	require.Equal(t, fleet.AdminNotificationVulnerabilitiesFailed, got.Type)
+	require.Equal(t, "failed to download the NVD feed", got.Details)
+
+	// a failure to create the notification does not panic
+	ds.NewAdminNotificationFunc = func(ctx context.Context, notification *fleet.AdminNotification) error {
+		return errors.New("db down")
+	}
+	handler(context.Background(), "cron_vulnerabilities", errors.New("again"))
+}
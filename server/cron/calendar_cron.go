@@ -34,6 +34,7 @@ func NewCalendarSchedule(
 		ctx, name, instanceID, interval, ds, ds,
 		schedule.WithAltLockID("calendar"),
 		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(NotifyAdminsOnJobError(ds, name, logger)),
 		schedule.WithJob(
 			"calendar_events_cleanup",
 			func(ctx context.Context) error {
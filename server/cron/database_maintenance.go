@@ -0,0 +1,302 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/service/schedule"
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	// maintenanceIntervalTolerance is subtracted from the interval of the
+	// maintenance tasks to decide if they are due, so that a task with an
+	// interval multiple of the schedule's is not delayed by a run because of
+	// the jitter of the schedule.
+	maintenanceIntervalTolerance = 5 * time.Minute
+	// analyzeTablesMinRows is the minimum number of rows of the tables whose
+	// index statistics are updated by the analyze_tables task, the statistics
+	// of smaller tables are cheap to keep up to date for MySQL.
+	analyzeTablesMinRows = 10_000
+	// optimizeTablesMinFreeBytes is the minimum unused space of the tables
+	// rebuilt by the optimize_tables task, which must also be at least a
+	// quarter of their size.
+	optimizeTablesMinFreeBytes = 1 << 30
+)
+
+// maintenanceTask is a task of the database_maintenance schedule.
+type maintenanceTask struct {
+	name string
+	// interval is the minimum time between two runs of the task, the task runs
+	// on every run of the schedule if 0.
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// NewDatabaseMaintenanceSchedule creates the schedule that maintains the
+// database: it prunes the expired and orphaned rows, records the size of the
+// tables to report their growth, and keeps the index statistics of the
+// tables up to date. Each task runs at its own interval, tracked in the
+// database, as a multiple of the schedule's interval.
+func NewDatabaseMaintenanceSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	cfg config.FleetConfig,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronDatabaseMaintenance)
+		defaultInterval = 1 * time.Hour
+	)
+	logger = kitlog.With(logger, "cron", name)
+	tasks := databaseMaintenanceTasks(ds, logger, cfg)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithAltLockID("database_maintenance"),
+		schedule.WithLogger(logger),
+		schedule.WithJobErrorHandler(NotifyAdminsOnJobError(ds, name, logger)),
+		schedule.WithJob(
+			"maintenance_tasks",
+			func(ctx context.Context) error {
+				return runMaintenanceTasks(ctx, ds, logger, tasks, time.Now())
+			},
+		),
+	)
+
+	return s, nil
+}
+
+func databaseMaintenanceTasks(ds fleet.Datastore, logger kitlog.Logger, cfg config.FleetConfig) []maintenanceTask {
+	tasks := []maintenanceTask{
+		// Prune the expired and orphaned rows first.
+		{
+			name: "distributed_query_campaigns",
+			fn: func(ctx context.Context) error {
+				_, err := ds.CleanupDistributedQueryCampaigns(ctx, time.Now().UTC())
+				return err
+			},
+		},
+		{
+			name: "incoming_hosts",
+			fn: func(ctx context.Context) error {
+				_, err := ds.CleanupIncomingHosts(ctx, time.Now())
+				return err
+			},
+		},
+		{
+			name: "carves",
+			fn: func(ctx context.Context) error {
+				_, err := ds.CleanupCarves(ctx, time.Now())
+				return err
+			},
+		},
+		{
+			name: "policy_membership",
+			fn: func(ctx context.Context) error {
+				return ds.CleanupPolicyMembership(ctx, time.Now())
+			},
+		},
+		{
+			name: "cleanup_host_operating_systems",
+			fn:   ds.CleanupHostOperatingSystems,
+		},
+		{
+			name: "cleanup_expired_password_reset_requests",
+			fn:   ds.CleanupExpiredPasswordResetRequests,
+		},
+		{
+			name: "cleanup_expired_mfa_challenges",
+			fn:   ds.CleanupExpiredMFAChallenges,
+		},
+		{
+			name: "cleanup_expired_sessions",
+			fn: func(ctx context.Context) error {
+				appConfig, err := ds.AppConfig(ctx)
+				if err != nil {
+					return err
+				}
+				// same expiration as validateSession in the service
+				idleTimeout := cfg.Session.Duration
+				if appConfig.SessionSettings.IdleTimeout > 0 {
+					idleTimeout = time.Duration(appConfig.SessionSettings.IdleTimeout) * time.Minute
+				}
+				maxLifetime := time.Duration(appConfig.SessionSettings.MaxSessionLifetime) * time.Minute
+				n, err := ds.CleanupExpiredSessions(ctx, idleTimeout, maxLifetime)
+				if err != nil {
+					return err
+				}
+				level.Debug(logger).Log("msg", "deleted expired sessions", "count", n)
+				return nil
+			},
+		},
+		{
+			name: "cleanup_expired_invites",
+			fn: func(ctx context.Context) error {
+				if cfg.App.InviteTokenValidityPeriod <= 0 {
+					return nil
+				}
+				n, err := ds.CleanupExpiredInvites(ctx, time.Now().Add(-cfg.App.InviteTokenValidityPeriod))
+				if err != nil {
+					return err
+				}
+				level.Debug(logger).Log("msg", "deleted expired invites", "count", n)
+				return nil
+			},
+		},
+		{
+			name: "query_results_cleanup",
+			fn: func(ctx context.Context) error {
+				appConfig, err := ds.AppConfig(ctx)
+				if err != nil {
+					return err
+				}
+				if appConfig.ServerSettings.QueryReportsDisabled {
+					if err := ds.CleanupGlobalDiscardQueryResults(ctx); err != nil {
+						return err
+					}
+				}
+				return ds.CleanupDiscardedQueryResults(ctx)
+			},
+		},
+		{
+			name: "cleanup_unused_script_contents",
+			fn:   ds.CleanupUnusedScriptContents,
+		},
+		{
+			name: "cleanup_activities",
+			fn: func(ctx context.Context) error {
+				appConfig, err := ds.AppConfig(ctx)
+				if err != nil {
+					return err
+				}
+				if !appConfig.ActivityExpirySettings.ActivityExpiryEnabled {
+					return nil
+				}
+				// A maxCount of 5,000 means that the cron job will keep the activities (and associated tables)
+				// sizes in control for deployments that generate (5k x 24 hours) ~120,000 activities per day.
+				const maxCount = 5000
+				return ds.CleanupActivitiesAndAssociatedData(ctx, maxCount, appConfig.ActivityExpirySettings.ActivityExpiryWindow)
+			},
+		},
+		{
+			name:     "cleanup_orphaned_host_rows",
+			interval: 24 * time.Hour,
+			fn: func(ctx context.Context) error {
+				n, err := ds.CleanupOrphanedHostRows(ctx)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					level.Info(logger).Log("msg", "deleted orphaned host rows", "count", n)
+				}
+				return nil
+			},
+		},
+		// Then record the size of the tables, used to select the tables to
+		// analyze and optimize.
+		{
+			name:     "table_sizes",
+			interval: 24 * time.Hour,
+			fn:       ds.SnapshotTableSizes,
+		},
+		{
+			name:     "analyze_tables",
+			interval: 7 * 24 * time.Hour,
+			fn: func(ctx context.Context) error {
+				return analyzeTables(ctx, ds, logger)
+			},
+		},
+	}
+
+	if cfg.Server.DatabaseOptimizeTables {
+		tasks = append(tasks, maintenanceTask{
+			name:     "optimize_tables",
+			interval: 30 * 24 * time.Hour,
+			fn: func(ctx context.Context) error {
+				return optimizeTables(ctx, ds, logger)
+			},
+		})
+	}
+	return tasks
+}
+
+// runMaintenanceTasks runs the tasks that are due at now, in order, and
+// records their runs. A failing task does not prevent the next ones from
+// running, the errors are returned once all the tasks ran.
+func runMaintenanceTasks(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, tasks []maintenanceTask, now time.Time) error {
+	runs, err := ds.ListDatabaseMaintenanceRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("list database maintenance runs: %w", err)
+	}
+	lastRuns := make(map[string]time.Time, len(runs))
+	for _, run := range runs {
+		lastRuns[run.Name] = run.LastRunAt
+	}
+
+	var errs []error
+	for _, task := range tasks {
+		if lastRun, ok := lastRuns[task.name]; ok && task.interval > 0 && now.Sub(lastRun) < task.interval-maintenanceIntervalTolerance {
+			continue
+		}
+
+		start := time.Now()
+		taskErr := task.fn(ctx)
+		run := &fleet.DatabaseMaintenanceRun{
+			Name:       task.name,
+			LastRunAt:  now,
+			DurationMS: uint(time.Since(start).Milliseconds()),
+		}
+		if taskErr != nil {
+			level.Error(logger).Log("msg", "database maintenance task failed", "task", task.name, "err", taskErr)
+			errs = append(errs, fmt.Errorf("%s: %w", task.name, taskErr))
+			run.Error = ptr.String(taskErr.Error())
+		}
+		if err := ds.RecordDatabaseMaintenanceRun(ctx, run); err != nil {
+			errs = append(errs, fmt.Errorf("record %s run: %w", task.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func analyzeTables(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	growth, err := ds.ListTableGrowth(ctx)
+	if err != nil {
+		return fmt.Errorf("list table growth: %w", err)
+	}
+	var tables []string
+	for _, t := range growth {
+		if t.Rows >= analyzeTablesMinRows {
+			tables = append(tables, t.TableName)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	level.Debug(logger).Log("msg", "analyzing tables", "tables", fmt.Sprint(tables))
+	return ds.AnalyzeTables(ctx, tables)
+}
+
+func optimizeTables(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	growth, err := ds.ListTableGrowth(ctx)
+	if err != nil {
+		return fmt.Errorf("list table growth: %w", err)
+	}
+	var tables []string
+	for _, t := range growth {
+		if t.FreeBytes >= optimizeTablesMinFreeBytes && t.FreeBytes*4 >= t.TotalBytes() {
+			tables = append(tables, t.TableName)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	level.Info(logger).Log("msg", "optimizing tables", "tables", fmt.Sprint(tables))
+	return ds.OptimizeTables(ctx, tables)
+}
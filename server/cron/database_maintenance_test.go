@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMaintenanceTasks(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	lastRuns := map[string]time.Time{
+		"hourly": now.Add(-time.Hour),
+		// the schedule ran a bit early, still due
+		"daily":  now.Add(-24*time.Hour + time.Minute),
+		"weekly": now.Add(-24 * time.Hour),
+	}
+	ds.ListDatabaseMaintenanceRunsFunc = func(ctx context.Context) ([]*fleet.DatabaseMaintenanceRun, error) {
+		var runs []*fleet.DatabaseMaintenanceRun
+		for name, lastRun := range lastRuns {
+			runs = append(runs, &fleet.DatabaseMaintenanceRun{Name: name, LastRunAt: lastRun})
+		}
+		return runs, nil
+	}
+	recorded := make(map[string]*fleet.DatabaseMaintenanceRun)
+	ds.RecordDatabaseMaintenanceRunFunc = func(ctx context.Context, run *fleet.DatabaseMaintenanceRun) error {
+		recorded[run.Name] = run
+		return nil
+	}
+
+	var ran []string
+	task := func(name string, interval time.Duration, err error) maintenanceTask {
+		return maintenanceTask{name: name, interval: interval, fn: func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	tasks := []maintenanceTask{
+		task("hourly", 0, errors.New("boom")),
+		task("daily", 24*time.Hour, nil),
+		task("weekly", 7*24*time.Hour, nil),
+		task("new", 30*24*time.Hour, nil),
+	}
+
+	err := runMaintenanceTasks(ctx, ds, kitlog.NewNopLogger(), tasks, now)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "hourly: boom")
+
+	// the failing task does not prevent the next ones from running
+	require.Equal(t, []string{"hourly", "daily", "new"}, ran)
+	require.Len(t, recorded, 3)
+	require.NotNil(t, recorded["hourly"].Error)
+	require.Equal(t, "boom", *recorded["hourly"].Error)
+	require.Nil(t, recorded["daily"].Error)
+	require.Equal(t, now, recorded["new"].LastRunAt)
+
+	// the runs are not recorded if they cannot be loaded
+	ds.ListDatabaseMaintenanceRunsFunc = func(ctx context.Context) ([]*fleet.DatabaseMaintenanceRun, error) {
+		return nil, errors.New("db down")
+	}
+	ran, recorded = nil, make(map[string]*fleet.DatabaseMaintenanceRun)
+	err = runMaintenanceTasks(ctx, ds, kitlog.NewNopLogger(), tasks, now)
+	require.ErrorContains(t, err, "db down")
+	require.Empty(t, ran)
+	require.Empty(t, recorded)
+}
+
+func TestDatabaseMaintenanceTasks(t *testing.T) {
+	ds := new(mock.Store)
+	names := func(tasks []maintenanceTask) []string {
+		var names []string
+		for _, task := range tasks {
+			names = append(names, task.name)
+		}
+		return names
+	}
+
+	var cfg config.FleetConfig
+	tasks := databaseMaintenanceTasks(ds, kitlog.NewNopLogger(), cfg)
+	require.Contains(t, names(tasks), "cleanup_expired_sessions")
+	require.Contains(t, names(tasks), "analyze_tables")
+	require.NotContains(t, names(tasks), "optimize_tables")
+
+	cfg.Server.DatabaseOptimizeTables = true
+	tasks = databaseMaintenanceTasks(ds, kitlog.NewNopLogger(), cfg)
+	require.Equal(t, "optimize_tables", tasks[len(tasks)-1].name)
+}
+
+func TestAnalyzeOptimizeTables(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	ds.ListTableGrowthFunc = func(ctx context.Context) ([]*fleet.TableGrowth, error) {
+		return []*fleet.TableGrowth{
+			{TableName: "hosts", Rows: 100_000, DataBytes: 4 << 30, IndexBytes: 1 << 30, FreeBytes: 2 << 30},
+			{TableName: "host_software", Rows: 50_000_000, DataBytes: 40 << 30, IndexBytes: 10 << 30, FreeBytes: 2 << 30},
+			{TableName: "labels", Rows: 100, DataBytes: 16 << 10, FreeBytes: 16 << 10},
+		}, nil
+	}
+	var analyzed, optimized []string
+	ds.AnalyzeTablesFunc = func(ctx context.Context, tables []string) error {
+		analyzed = tables
+		return nil
+	}
+	ds.OptimizeTablesFunc = func(ctx context.Context, tables []string) error {
+		optimized = tables
+		return nil
+	}
+
+	require.NoError(t, analyzeTables(ctx, ds, kitlog.NewNopLogger()))
+	require.Equal(t, []string{"hosts", "host_software"}, analyzed)
+
+	// only the tables with a large amount of unused space relative to their
+	// size are optimized
+	require.NoError(t, optimizeTables(ctx, ds, kitlog.NewNopLogger()))
+	require.Equal(t, []string{"hosts"}, optimized)
+
+	ds.ListTableGrowthFunc = func(ctx context.Context) ([]*fleet.TableGrowth, error) {
+		return nil, nil
+	}
+	ds.AnalyzeTablesFuncInvoked = false
+	require.NoError(t, analyzeTables(ctx, ds, kitlog.NewNopLogger()))
+	require.False(t, ds.AnalyzeTablesFuncInvoked)
+}
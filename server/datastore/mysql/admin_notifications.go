@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewAdminNotification(ctx context.Context, notification *fleet.AdminNotification) error {
+	const stmt = `
+		INSERT INTO admin_notifications
+			(notification_key, type, severity, title, details)
+		VALUES
+			(?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			type = VALUES(type),
+			severity = VALUES(severity),
+			title = VALUES(title),
+			details = VALUES(details),
+			occurrences = occurrences + 1,
+			updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		notification.Key,
+		notification.Type,
+		notification.Severity,
+		notification.Title,
+		notification.Details,
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert admin notification")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListAdminNotifications(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error) {
+	stmt := `
+		SELECT
+			n.id,
+			n.notification_key,
+			n.type,
+			n.severity,
+			n.title,
+			n.details,
+			n.occurrences,
+			n.created_at,
+			n.updated_at,
+			r.notification_id IS NOT NULL AS ` + "`read`" + `
+		FROM admin_notifications n
+		LEFT JOIN admin_notification_reads r
+			ON r.notification_id = n.id AND r.user_id = ?
+		WHERE true`
+	args := []interface{}{userID}
+
+	if opts.UnreadOnly {
+		stmt += " AND r.notification_id IS NULL"
+	}
+	if opts.UpdatedSince != nil {
+		stmt += " AND n.updated_at >= ?"
+		args = append(args, *opts.UpdatedSince)
+	}
+
+	listOpts := opts.ListOptions
+	listOpts.OrderKey = "n.updated_at"
+	listOpts.OrderDirection = fleet.OrderDescending
+	listOpts.After = ""
+	listOpts.IncludeMetadata = true
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, &listOpts)
+
+	var notifications []*fleet.AdminNotification
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &notifications, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "select admin notifications")
+	}
+
+	meta := &fleet.PaginationMetadata{HasPreviousResults: listOpts.Page > 0}
+	if len(notifications) > int(listOpts.PerPage) {
+		meta.HasNextResults = true
+		notifications = notifications[:len(notifications)-1]
+	}
+	return notifications, meta, nil
+}
+
+func (ds *Datastore) CountUnreadAdminNotifications(ctx context.Context, userID uint) (int, error) {
+	const stmt = `
+		SELECT COUNT(*)
+		FROM admin_notifications n
+		LEFT JOIN admin_notification_reads r
+			ON r.notification_id = n.id AND r.user_id = ?
+		WHERE r.notification_id IS NULL`
+
+	var count int
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &count, stmt, userID); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "count unread admin notifications")
+	}
+	return count, nil
+}
+
+func (ds *Datastore) MarkAdminNotificationsRead(ctx context.Context, userID uint, notificationIDs []uint) error {
+	stmt := `
+		INSERT IGNORE INTO admin_notification_reads
+			(notification_id, user_id)
+		SELECT id, ? FROM admin_notifications`
+	args := []interface{}{userID}
+
+	if len(notificationIDs) > 0 {
+		stmt += " WHERE id IN (?)"
+		args = append(args, notificationIDs)
+
+		var err error
+		stmt, args, err = sqlx.In(stmt, args...)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build mark admin notifications read statement")
+		}
+	}
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "mark admin notifications read")
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteAdminNotification(ctx context.Context, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM admin_notifications WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete admin notification")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("AdminNotification").WithID(id))
+	}
+	return nil
+}
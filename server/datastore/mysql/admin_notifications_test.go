@@ -0,0 +1,177 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminNotifications(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"NewAndList", testAdminNotificationsNewAndList},
+		{"ReadState", testAdminNotificationsReadState},
+		{"Delete", testAdminNotificationsDelete},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testAdminNotificationsNewAndList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	u := test.NewUser(t, ds, "admin", "admin@example.com", true)
+
+	list, meta, err := ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, list)
+	require.False(t, meta.HasNextResults)
+
+	n1 := fleet.NewCronJobFailedNotification(string(fleet.CronVulnerabilities), "cron_vulnerabilities", errors.New("feed down"))
+	require.NoError(t, ds.NewAdminNotification(ctx, n1))
+	n2 := fleet.NewCronJobFailedNotification(string(fleet.CronCleanupsThenAggregation), "cleanup", errors.New("boom"))
+	require.NoError(t, ds.NewAdminNotification(ctx, n2))
+
+	// the same event raised again updates the existing notification
+	n1 = fleet.NewCronJobFailedNotification(string(fleet.CronVulnerabilities), "cron_vulnerabilities", errors.New("feed still down"))
+	require.NoError(t, ds.NewAdminNotification(ctx, n1))
+
+	list, _, err = ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	byType := make(map[fleet.AdminNotificationType]*fleet.AdminNotification, len(list))
+	for _, n := range list {
+		byType[n.Type] = n
+	}
+	vuln := byType[fleet.AdminNotificationVulnerabilitiesFailed]
+	require.NotNil(t, vuln)
+	require.Equal(t, "feed still down", vuln.Details)
+	require.EqualValues(t, 2, vuln.Occurrences)
+	require.Equal(t, fleet.AdminNotificationSeverityError, vuln.Severity)
+	require.False(t, vuln.Read)
+	cron := byType[fleet.AdminNotificationCronJobFailed]
+	require.NotNil(t, cron)
+	require.Equal(t, "boom", cron.Details)
+	require.EqualValues(t, 1, cron.Occurrences)
+
+	// pagination
+	list, meta, err = ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{ListOptions: fleet.ListOptions{PerPage: 1}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.True(t, meta.HasNextResults)
+	require.False(t, meta.HasPreviousResults)
+	list, meta, err = ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{ListOptions: fleet.ListOptions{PerPage: 1, Page: 1}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.False(t, meta.HasNextResults)
+	require.True(t, meta.HasPreviousResults)
+
+	// updated since
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE admin_notifications SET updated_at = ? WHERE id = ?`, time.Now().Add(-48*time.Hour), cron.ID)
+		return err
+	})
+	list, _, err = ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{UpdatedSince: ptr.Time(time.Now().Add(-24 * time.Hour))})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, vuln.ID, list[0].ID)
+}
+
+func testAdminNotificationsReadState(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	u1 := test.NewUser(t, ds, "admin1", "admin1@example.com", true)
+	u2 := test.NewUser(t, ds, "admin2", "admin2@example.com", true)
+
+	for _, job := range []string{"a", "b", "c"} {
+		require.NoError(t, ds.NewAdminNotification(ctx, fleet.NewCronJobFailedNotification(string(fleet.CronCleanupsThenAggregation), job, errors.New(job))))
+	}
+	list, _, err := ds.ListAdminNotifications(ctx, u1.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	count, err := ds.CountUnreadAdminNotifications(ctx, u1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// mark one as read for u1, twice to check it is idempotent
+	require.NoError(t, ds.MarkAdminNotificationsRead(ctx, u1.ID, []uint{list[0].ID}))
+	require.NoError(t, ds.MarkAdminNotificationsRead(ctx, u1.ID, []uint{list[0].ID}))
+	count, err = ds.CountUnreadAdminNotifications(ctx, u1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = ds.CountUnreadAdminNotifications(ctx, u2.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	unread, _, err := ds.ListAdminNotifications(ctx, u1.ID, fleet.AdminNotificationListOptions{UnreadOnly: true})
+	require.NoError(t, err)
+	require.Len(t, unread, 2)
+	for _, n := range unread {
+		require.NotEqual(t, list[0].ID, n.ID)
+		require.False(t, n.Read)
+	}
+
+	// the event raised again does not change the read state
+	require.NoError(t, ds.NewAdminNotification(ctx, &fleet.AdminNotification{
+		Key:      list[0].Key,
+		Type:     list[0].Type,
+		Severity: list[0].Severity,
+		Title:    list[0].Title,
+		Details:  "again",
+	}))
+	all, _, err := ds.ListAdminNotifications(ctx, u1.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	for _, n := range all {
+		require.Equal(t, n.ID == list[0].ID, n.Read)
+	}
+
+	// mark all as read for u2
+	require.NoError(t, ds.MarkAdminNotificationsRead(ctx, u2.ID, nil))
+	count, err = ds.CountUnreadAdminNotifications(ctx, u2.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	count, err = ds.CountUnreadAdminNotifications(ctx, u1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
+
+func testAdminNotificationsDelete(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	u := test.NewUser(t, ds, "admin", "admin@example.com", true)
+
+	n := fleet.NewCronJobFailedNotification(string(fleet.CronCleanupsThenAggregation), "job", errors.New("boom"))
+	require.NoError(t, ds.NewAdminNotification(ctx, n))
+	list, _, err := ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NoError(t, ds.MarkAdminNotificationsRead(ctx, u.ID, nil))
+
+	require.NoError(t, ds.DeleteAdminNotification(ctx, list[0].ID))
+	err = ds.DeleteAdminNotification(ctx, list[0].ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the event raised again after the dismissal creates a new, unread,
+	// notification
+	require.NoError(t, ds.NewAdminNotification(ctx, n))
+	newList, _, err := ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{})
+	require.NoError(t, err)
+	require.Len(t, newList, 1)
+	require.NotEqual(t, list[0].ID, newList[0].ID)
+	require.False(t, newList[0].Read)
+	require.EqualValues(t, 1, newList[0].Occurrences)
+}
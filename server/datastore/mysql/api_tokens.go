@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const apiTokenSelectColumns = `
+	id,
+	name,
+	team_id,
+	scope,
+	created_by_user_id,
+	last_used_at,
+	created_at
+`
+
+func (ds *Datastore) NewAPIToken(ctx context.Context, token *fleet.APIToken, tokenHash string) (*fleet.APIToken, error) {
+	const stmt = `
+		INSERT INTO api_tokens (
+			name,
+			team_id,
+			scope,
+			token_hash,
+			created_by_user_id
+		)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, token.Name, token.TeamID, token.Scope, tokenHash, token.CreatedByUserID)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("APIToken", token.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "inserting api token")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return ds.apiTokenByID(ctx, ds.writer(ctx), uint(id))
+}
+
+func (ds *Datastore) APIToken(ctx context.Context, id uint) (*fleet.APIToken, error) {
+	return ds.apiTokenByID(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) apiTokenByID(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.APIToken, error) {
+	stmt := `SELECT ` + apiTokenSelectColumns + ` FROM api_tokens WHERE id = ?`
+
+	var tok fleet.APIToken
+	if err := sqlx.GetContext(ctx, q, &tok, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("APIToken").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting api token by id")
+	}
+	return &tok, nil
+}
+
+func (ds *Datastore) APITokenByHash(ctx context.Context, tokenHash string) (*fleet.APIToken, error) {
+	stmt := `SELECT ` + apiTokenSelectColumns + ` FROM api_tokens WHERE token_hash = ?`
+
+	var tok fleet.APIToken
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &tok, stmt, tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("APIToken").WithName("<token redacted>"))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting api token by hash")
+	}
+	return &tok, nil
+}
+
+func (ds *Datastore) ListAPITokens(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error) {
+	stmt := `SELECT ` + apiTokenSelectColumns + ` FROM api_tokens`
+	var args []any
+	if teamID != nil {
+		stmt += ` WHERE team_id = ?`
+		args = append(args, *teamID)
+	}
+	stmt += ` ORDER BY team_id, name`
+
+	var toks []*fleet.APIToken
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &toks, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing api tokens")
+	}
+	return toks, nil
+}
+
+func (ds *Datastore) DeleteAPIToken(ctx context.Context, id uint) error {
+	return ds.deleteEntity(ctx, apiTokensTable, id)
+}
+
+func (ds *Datastore) MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	const stmt = `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, usedAt, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "updating api token last used")
+	}
+	return nil
+}
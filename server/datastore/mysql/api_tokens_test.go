@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokens(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testAPITokensCRUD},
+		{"DeleteCascade", testAPITokensDeleteCascade},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testAPITokensCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user, err := ds.NewUser(ctx, &fleet.User{
+		Password:   []byte("supersecret"),
+		Email:      "admin@example.com",
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	})
+	require.NoError(t, err)
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	tok1, err := ds.NewAPIToken(ctx, &fleet.APIToken{
+		Name:            "inventory",
+		TeamID:          team1.ID,
+		Scope:           fleet.APITokenScopeInventoryRead,
+		CreatedByUserID: user.ID,
+	}, fleet.HashAPITokenSecret("secret1"))
+	require.NoError(t, err)
+	require.NotZero(t, tok1.ID)
+	require.Equal(t, "inventory", tok1.Name)
+	require.Equal(t, team1.ID, tok1.TeamID)
+	require.Equal(t, fleet.APITokenScopeInventoryRead, tok1.Scope)
+	require.Nil(t, tok1.LastUsedAt)
+
+	// same name in the same team fails
+	_, err = ds.NewAPIToken(ctx, &fleet.APIToken{
+		Name:            "inventory",
+		TeamID:          team1.ID,
+		Scope:           fleet.APITokenScopeScripts,
+		CreatedByUserID: user.ID,
+	}, fleet.HashAPITokenSecret("secret2"))
+	require.Error(t, err)
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	// same name in another team is fine
+	tok2, err := ds.NewAPIToken(ctx, &fleet.APIToken{
+		Name:            "inventory",
+		TeamID:          team2.ID,
+		Scope:           fleet.APITokenScopeMDM,
+		CreatedByUserID: user.ID,
+	}, fleet.HashAPITokenSecret("secret2"))
+	require.NoError(t, err)
+
+	got, err := ds.APIToken(ctx, tok1.ID)
+	require.NoError(t, err)
+	require.Equal(t, tok1, got)
+
+	got, err = ds.APITokenByHash(ctx, fleet.HashAPITokenSecret("secret2"))
+	require.NoError(t, err)
+	require.Equal(t, tok2.ID, got.ID)
+
+	_, err = ds.APITokenByHash(ctx, fleet.HashAPITokenSecret("nosuchsecret"))
+	require.True(t, fleet.IsNotFound(err))
+
+	toks, err := ds.ListAPITokens(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, toks, 2)
+	require.Equal(t, tok1.ID, toks[0].ID)
+	require.Equal(t, tok2.ID, toks[1].ID)
+
+	toks, err = ds.ListAPITokens(ctx, &team2.ID)
+	require.NoError(t, err)
+	require.Len(t, toks, 1)
+	require.Equal(t, tok2.ID, toks[0].ID)
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, ds.MarkAPITokenUsed(ctx, tok1.ID, usedAt))
+	got, err = ds.APIToken(ctx, tok1.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastUsedAt)
+	require.True(t, usedAt.Equal(*got.LastUsedAt))
+
+	require.NoError(t, ds.DeleteAPIToken(ctx, tok1.ID))
+	_, err = ds.APIToken(ctx, tok1.ID)
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.DeleteAPIToken(ctx, tok1.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testAPITokensDeleteCascade(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user, err := ds.NewUser(ctx, &fleet.User{
+		Password:   []byte("supersecret"),
+		Email:      "admin@example.com",
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	})
+	require.NoError(t, err)
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	tok, err := ds.NewAPIToken(ctx, &fleet.APIToken{
+		Name:            "scripts",
+		TeamID:          team.ID,
+		Scope:           fleet.APITokenScopeScripts,
+		CreatedByUserID: user.ID,
+	}, fleet.HashAPITokenSecret("secret"))
+	require.NoError(t, err)
+
+	// deleting the team deletes its tokens
+	require.NoError(t, ds.DeleteTeam(ctx, team.ID))
+	_, err = ds.APIToken(ctx, tok.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
@@ -69,7 +69,7 @@ func (ds *Datastore) SaveAppConfig(ctx context.Context, info *fleet.AppConfig) e
 
 func (ds *Datastore) VerifyEnrollSecret(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
 	var s fleet.EnrollSecret
-	err := sqlx.GetContext(ctx, ds.reader(ctx), &s, "SELECT team_id FROM enroll_secrets WHERE secret = ?", secret)
+	err := sqlx.GetContext(ctx, ds.reader(ctx), &s, "SELECT team_id, labels, custom_fields FROM enroll_secrets WHERE secret = ?", secret)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ctxerr.Wrap(ctx, notFound("EnrollSecret"), "no matching secret found")
@@ -133,18 +133,36 @@ func applyEnrollSecretsDB(ctx context.Context, q sqlx.ExtContext, teamID *uint,
 
 	// finally, insert the new secrets, using the existing created_at timestamp
 	// if available.
-	const insStmt = `INSERT INTO enroll_secrets (secret, team_id, created_at) VALUES %s`
+	const insStmt = `INSERT INTO enroll_secrets (secret, team_id, created_at, labels, custom_fields) VALUES %s`
 	if len(newSecrets) > 0 {
 		var args []interface{}
 		defaultCreatedAt := time.Now()
-		sql := fmt.Sprintf(insStmt, strings.TrimSuffix(strings.Repeat(`(?,?,?),`, len(newSecrets)), ","))
+		sql := fmt.Sprintf(insStmt, strings.TrimSuffix(strings.Repeat(`(?,?,?,?,?),`, len(newSecrets)), ","))
 
 		for _, s := range secrets {
 			secretCreatedAt := defaultCreatedAt
 			if ts := secretsCreatedAt[s.Secret]; ts != nil {
 				secretCreatedAt = *ts
 			}
-			args = append(args, s.Secret, teamID, secretCreatedAt)
+
+			// labels and custom_fields are NULL when the secret has no host
+			// rules.
+			var labels, customFields interface{}
+			if len(s.Labels) > 0 {
+				b, err := json.Marshal(s.Labels)
+				if err != nil {
+					return ctxerr.Wrap(ctx, err, "marshal secret labels")
+				}
+				labels = b
+			}
+			if len(s.CustomFields) > 0 {
+				b, err := json.Marshal(s.CustomFields)
+				if err != nil {
+					return ctxerr.Wrap(ctx, err, "marshal secret custom fields")
+				}
+				customFields = b
+			}
+			args = append(args, s.Secret, teamID, secretCreatedAt, labels, customFields)
 		}
 		if _, err := q.ExecContext(ctx, sql, args...); err != nil {
 			if isDuplicate(err) {
@@ -163,7 +181,7 @@ func (ds *Datastore) GetEnrollSecrets(ctx context.Context, teamID *uint) ([]*fle
 
 func getEnrollSecretsDB(ctx context.Context, q sqlx.QueryerContext, teamID *uint) ([]*fleet.EnrollSecret, error) {
 	var args []interface{}
-	sql := "SELECT secret, team_id, created_at FROM enroll_secrets WHERE "
+	sql := "SELECT secret, team_id, created_at, labels, custom_fields FROM enroll_secrets WHERE "
 	// MySQL requires comparing NULL with IS. NULL = NULL evaluates to FALSE.
 	if teamID == nil {
 		sql += "team_id IS NULL"
@@ -29,6 +29,7 @@ func TestAppConfig(t *testing.T) {
 		{"EnrollSecrets", testAppConfigEnrollSecrets},
 		{"EnrollSecretsCaseSensitive", testAppConfigEnrollSecretsCaseSensitive},
 		{"EnrollSecretRoundtrip", testAppConfigEnrollSecretRoundtrip},
+		{"EnrollSecretHostRules", testAppConfigEnrollSecretHostRules},
 		{"EnrollSecretUniqueness", testAppConfigEnrollSecretUniqueness},
 		{"AggregateEnrollSecretPerTeam", testAggregateEnrollSecretPerTeam},
 		{"Defaults", testAppConfigDefaults},
@@ -315,6 +316,54 @@ func testAppConfigEnrollSecretRoundtrip(t *testing.T, ds *Datastore) {
 	require.Len(t, secrets, 2)
 }
 
+func testAppConfigEnrollSecretHostRules(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	defer TruncateTables(t, ds)
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	err = ds.ApplyEnrollSecrets(ctx, &team1.ID, []*fleet.EnrollSecret{
+		{Secret: "kiosk", Labels: []string{"kiosk", "lobby"}, CustomFields: fleet.HostCustomFieldValues{"role": "kiosk"}},
+		{Secret: "plain"},
+	})
+	require.NoError(t, err)
+
+	secrets, err := ds.GetEnrollSecrets(ctx, &team1.ID)
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Secret < secrets[j].Secret })
+	assert.Equal(t, fleet.SliceString{"kiosk", "lobby"}, secrets[0].Labels)
+	assert.Equal(t, fleet.HostCustomFieldValues{"role": "kiosk"}, secrets[0].CustomFields)
+	assert.Empty(t, secrets[1].Labels)
+	assert.Empty(t, secrets[1].CustomFields)
+
+	secrets, err = ds.TeamEnrollSecrets(ctx, team1.ID)
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+
+	secret, err := ds.VerifyEnrollSecret(ctx, "kiosk")
+	require.NoError(t, err)
+	require.Equal(t, &team1.ID, secret.TeamID)
+	assert.Equal(t, fleet.SliceString{"kiosk", "lobby"}, secret.Labels)
+	assert.Equal(t, fleet.HostCustomFieldValues{"role": "kiosk"}, secret.CustomFields)
+
+	secret, err = ds.VerifyEnrollSecret(ctx, "plain")
+	require.NoError(t, err)
+	assert.Empty(t, secret.Labels)
+	assert.Empty(t, secret.CustomFields)
+
+	// re-applying the secrets updates their host rules
+	err = ds.ApplyEnrollSecrets(ctx, &team1.ID, []*fleet.EnrollSecret{
+		{Secret: "kiosk", Labels: []string{"kiosk"}},
+	})
+	require.NoError(t, err)
+	secret, err = ds.VerifyEnrollSecret(ctx, "kiosk")
+	require.NoError(t, err)
+	assert.Equal(t, fleet.SliceString{"kiosk"}, secret.Labels)
+	assert.Empty(t, secret.CustomFields)
+}
+
 func testAppConfigEnrollSecretUniqueness(t *testing.T, ds *Datastore) {
 	defer TruncateTables(t, ds)
 
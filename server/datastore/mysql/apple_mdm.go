@@ -68,13 +68,20 @@ INSERT INTO
 		// filled in.
 		profileID, _ = res.LastInsertId()
 
-		for i := range cp.Labels {
-			cp.Labels[i].ProfileUUID = profUUID
+		labels := fleet.JoinConfigurationProfileLabels(cp.Labels, cp.LabelsIncludeAny, cp.LabelsExcludeAny)
+		for i := range labels {
+			labels[i].ProfileUUID = profUUID
 		}
-		if err := batchSetProfileLabelAssociationsDB(ctx, tx, cp.Labels, "darwin"); err != nil {
+		if err := batchSetProfileLabelAssociationsDB(ctx, tx, labels, "darwin"); err != nil {
 			return ctxerr.Wrap(ctx, err, "inserting darwin profile label associations")
 		}
 
+		if cp.Rollout != nil {
+			if err := upsertMDMProfileRolloutDB(ctx, tx, profUUID, *cp.Rollout, true); err != nil {
+				return ctxerr.Wrap(ctx, err, "inserting darwin profile rollout")
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -88,6 +95,7 @@ INSERT INTO
 		Name:         cp.Name,
 		Mobileconfig: cp.Mobileconfig,
 		TeamID:       cp.TeamID,
+		Rollout:      cp.Rollout,
 	}, nil
 }
 
@@ -219,10 +227,8 @@ WHERE
 		if err != nil {
 			return nil, err
 		}
-		if len(labels) > 0 {
-			// ensure we leave Labels nil if there are none
-			res.Labels = labels
-		}
+		// labels are left nil if there are none
+		res.Labels, res.LabelsIncludeAny, res.LabelsExcludeAny = fleet.SplitConfigurationProfileLabels(labels)
 	}
 
 	return &res, nil
@@ -525,6 +531,110 @@ func (ds *Datastore) GetMDMAppleCommandRequestType(ctx context.Context, commandU
 	return rt, err
 }
 
+// noPendingRefetchCommandCondition is the SQL condition that the device
+// enrollment aliased as "ne" has no pending refetch command of the request
+// type provided as argument, see noPendingRefetchCommandArgs.
+const noPendingRefetchCommandCondition = `
+	NOT EXISTS (
+		SELECT 1
+		FROM nano_enrollment_queue neq
+		JOIN nano_commands nc
+			ON nc.command_uuid = neq.command_uuid
+		LEFT JOIN nano_command_results ncr
+			ON ncr.id = neq.id AND ncr.command_uuid = neq.command_uuid
+		WHERE
+			neq.id = ne.id AND
+			neq.active = 1 AND
+			nc.request_type = ? AND
+			neq.command_uuid LIKE ? AND
+			(ncr.status IS NULL OR ncr.status = ?)
+	)`
+
+func noPendingRefetchCommandArgs(requestType string) []any {
+	return []any{requestType, fleet.RefetchMDMCommandUUIDPrefix + "%", fleet.MDMAppleStatusNotNow}
+}
+
+func (ds *Datastore) ListIOSAndIPadOSToRefetch(ctx context.Context, interval time.Duration) ([]string, error) {
+	stmt := `
+SELECT
+	h.uuid
+FROM
+	hosts h
+	JOIN nano_enrollments ne
+		ON ne.device_id = h.uuid
+WHERE
+	h.platform IN ('ios', 'ipados') AND
+	ne.enabled = 1 AND
+	ne.type = 'Device' AND
+	(h.detail_updated_at < DATE_SUB(NOW(), INTERVAL ? SECOND) OR h.refetch_requested = 1) AND` +
+		noPendingRefetchCommandCondition
+
+	args := append([]any{int(interval.Seconds())}, noPendingRefetchCommandArgs("DeviceInformation")...)
+	var uuids []string
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &uuids, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list iOS and iPadOS hosts to refetch")
+	}
+	return uuids, nil
+}
+
+func (ds *Datastore) ListAppleHostsForMDMSoftwareRefetch(ctx context.Context, interval time.Duration) ([]string, error) {
+	// hosts enrolled in osquery report their software via osquery, the
+	// software refreshed via MDM would be overwritten.
+	stmt := `
+SELECT
+	h.uuid
+FROM
+	hosts h
+	JOIN nano_enrollments ne
+		ON ne.device_id = h.uuid
+	LEFT JOIN host_updates hu
+		ON hu.host_id = h.id
+WHERE
+	h.platform IN ('darwin', 'ios', 'ipados') AND
+	h.node_key IS NULL AND
+	ne.enabled = 1 AND
+	ne.type = 'Device' AND
+	(COALESCE(hu.software_updated_at, h.created_at) < DATE_SUB(NOW(), INTERVAL ? SECOND) OR h.refetch_requested = 1) AND` +
+		noPendingRefetchCommandCondition
+
+	args := append([]any{int(interval.Seconds())}, noPendingRefetchCommandArgs("InstalledApplicationList")...)
+	var uuids []string
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &uuids, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list Apple hosts for MDM software refetch")
+	}
+	return uuids, nil
+}
+
+func (ds *Datastore) ListHostsWithNotNowMDMAppleCommands(ctx context.Context, requestType string, retryInterval, maxAge time.Duration) ([]string, error) {
+	// the result of a command is updated each time the device responds, so
+	// updated_at is the time of the last NotNow response.
+	stmt := `
+SELECT DISTINCT
+	ne.device_id
+FROM
+	nano_command_results ncr
+	JOIN nano_enrollment_queue neq
+		ON neq.id = ncr.id AND neq.command_uuid = ncr.command_uuid
+	JOIN nano_commands nc
+		ON nc.command_uuid = ncr.command_uuid
+	JOIN nano_enrollments ne
+		ON ne.id = ncr.id
+WHERE
+	nc.request_type = ? AND
+	ncr.status = ? AND
+	neq.active = 1 AND
+	ne.enabled = 1 AND
+	ncr.updated_at < DATE_SUB(NOW(), INTERVAL ? SECOND) AND
+	neq.created_at >= DATE_SUB(NOW(), INTERVAL ? SECOND)`
+
+	var uuids []string
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &uuids, stmt, requestType, fleet.MDMAppleStatusNotNow,
+		int(retryInterval.Seconds()), int(maxAge.Seconds())); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list hosts with NotNow MDM commands")
+	}
+	return uuids, nil
+}
+
 func (ds *Datastore) GetMDMAppleCommandResults(ctx context.Context, commandUUID string) ([]*fleet.MDMCommandResult, error) {
 	query := `
 SELECT
@@ -743,6 +853,15 @@ func ingestMDMAppleDeviceFromCheckinDB(
 	}
 }
 
+// mdmAppleHostPlatform returns the platform of an Apple host ingested via
+// MDM, defaulting to macOS if unknown.
+func mdmAppleHostPlatform(platform string) string {
+	if fleet.IsApplePlatform(platform) {
+		return platform
+	}
+	return "darwin"
+}
+
 func updateMDMAppleHostDB(
 	ctx context.Context,
 	tx sqlx.ExtContext,
@@ -766,7 +885,7 @@ func updateMDMAppleHostDB(
 		mdmHost.HardwareSerial,
 		mdmHost.UUID,
 		mdmHost.HardwareModel,
-		"darwin",
+		mdmAppleHostPlatform(mdmHost.Platform),
 		1,
 		// Set osquery_host_id to the device UUID only if it is not already set.
 		mdmHost.UUID,
@@ -812,7 +931,7 @@ func insertMDMAppleHostDB(
 		mdmHost.HardwareSerial,
 		mdmHost.UUID,
 		mdmHost.HardwareModel,
-		"darwin",
+		mdmAppleHostPlatform(mdmHost.Platform),
 		"2000-01-01 00:00:00",
 		"2000-01-01 00:00:00",
 		mdmHost.UUID,
@@ -832,6 +951,10 @@ func insertMDMAppleHostDB(
 
 	mdmHost.ID = uint(id)
 
+	if err := insertHostPendingApprovalDB(ctx, tx, appCfg, mdmHost.ID, fleet.HostEnrollmentSourceMDM); err != nil {
+		return err
+	}
+
 	if err := upsertMDMAppleHostDisplayNamesDB(ctx, tx, *mdmHost); err != nil {
 		return ctxerr.Wrap(ctx, err, "ingest mdm apple host upsert display names")
 	}
@@ -896,7 +1019,7 @@ func (ds *Datastore) IngestMDMAppleDevicesFromDEPSync(ctx context.Context, devic
 			SELECT
 				us.hardware_serial,
 				COALESCE(GROUP_CONCAT(DISTINCT us.hardware_model), ''),
-				'darwin' AS platform,
+				COALESCE(MAX(us.platform), 'darwin') AS platform,
 				'2000-01-01 00:00:00' AS last_enrolled_at,
 				'2000-01-01 00:00:00' AS detail_updated_at,
 				NULL AS osquery_host_id,
@@ -935,6 +1058,7 @@ func (ds *Datastore) IngestMDMAppleDevicesFromDEPSync(ctx context.Context, devic
 				h.id,
 				h.hardware_model,
 				h.hardware_serial,
+				h.platform,
 				COALESCE(hmdm.enrolled, 0) as enrolled
 			FROM hosts h
 			LEFT JOIN host_mdm hmdm ON hmdm.host_id = h.id
@@ -1095,16 +1219,19 @@ func upsertMDMAppleHostLabelMembershipDB(ctx context.Context, tx sqlx.ExtContext
 	// query results are received; however, we want to insert pending MDM hosts
 	// now because it may still be some time before osquery is running on these
 	// devices. Because these are Apple devices, we're adding them to the "All
-	// Hosts" and "macOS" labels.
-	labelIDs := []uint{}
-	err := sqlx.SelectContext(ctx, tx, &labelIDs, `SELECT id FROM labels WHERE label_type = 1 AND (name = 'All Hosts' OR name = 'macOS')`)
+	// Hosts" label and macOS devices to the "macOS" label.
+	var labels []struct {
+		ID   uint   `db:"id"`
+		Name string `db:"name"`
+	}
+	err := sqlx.SelectContext(ctx, tx, &labels, `SELECT id, name FROM labels WHERE label_type = 1 AND (name = 'All Hosts' OR name = 'macOS')`)
 	switch {
 	case err != nil:
 		return ctxerr.Wrap(ctx, err, "get builtin labels")
-	case len(labelIDs) != 2:
+	case len(labels) != 2:
 		// Builtin labels can get deleted so it is important that we check that
 		// they still exist before we continue.
-		level.Error(logger).Log("err", fmt.Sprintf("expected 2 builtin labels but got %d", len(labelIDs)))
+		level.Error(logger).Log("err", fmt.Sprintf("expected 2 builtin labels but got %d", len(labels)))
 		return nil
 	default:
 		// continue
@@ -1113,8 +1240,13 @@ func upsertMDMAppleHostLabelMembershipDB(ctx context.Context, tx sqlx.ExtContext
 	parts := []string{}
 	args := []interface{}{}
 	for _, h := range hosts {
-		parts = append(parts, "(?,?),(?,?)")
-		args = append(args, h.ID, labelIDs[0], h.ID, labelIDs[1])
+		for _, l := range labels {
+			if l.Name == "macOS" && mdmAppleHostPlatform(h.Platform) != "darwin" {
+				continue
+			}
+			parts = append(parts, "(?,?)")
+			args = append(args, h.ID, l.ID)
+		}
 	}
 	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
 			INSERT INTO label_membership (host_id, label_id) VALUES %s
@@ -1132,6 +1264,8 @@ func (ds *Datastore) deleteMDMOSCustomSettingsForHost(ctx context.Context, tx sq
 
 	tableMap := map[string][]string{
 		"darwin":  {"host_mdm_apple_profiles", "host_mdm_apple_declarations"},
+		"ios":     {"host_mdm_apple_profiles", "host_mdm_apple_declarations"},
+		"ipados":  {"host_mdm_apple_profiles", "host_mdm_apple_declarations"},
 		"windows": {"host_mdm_windows_profiles"},
 	}
 
@@ -1163,7 +1297,7 @@ func (ds *Datastore) MDMTurnOff(ctx context.Context, uuid string) error {
 			return ctxerr.Wrap(ctx, err, "getting host info from UUID")
 		}
 
-		if host.Platform != "darwin" && host.Platform != "windows" {
+		if !fleet.IsApplePlatform(host.Platform) && host.Platform != "windows" {
 			return ctxerr.Errorf(ctx, "unsupported host platform: %s", host.Platform)
 		}
 
@@ -1202,11 +1336,11 @@ func (ds *Datastore) MDMTurnOff(ctx context.Context, uuid string) error {
 func unionSelectDevices(devices []godep.Device) (stmt string, args []interface{}) {
 	for i, d := range devices {
 		if i == 0 {
-			stmt = "SELECT ? hardware_serial, ? hardware_model"
+			stmt = "SELECT ? hardware_serial, ? hardware_model, ? platform"
 		} else {
-			stmt += " UNION SELECT ?, ?"
+			stmt += " UNION SELECT ?, ?, ?"
 		}
-		args = append(args, d.SerialNumber, d.Model)
+		args = append(args, d.SerialNumber, d.Model, fleet.ApplePlatformFromModel(d.DeviceFamily))
 	}
 
 	return stmt, args
@@ -1324,6 +1458,25 @@ func (ds *Datastore) GetNanoMDMEnrollment(ctx context.Context, id string) (*flee
 	return &nanoEnroll, nil
 }
 
+func (ds *Datastore) ListNanoMDMUserEnrollmentDeviceIDs(ctx context.Context, deviceIDs []string) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+
+	stmt, args, err := sqlx.In(`SELECT DISTINCT device_id
+		FROM nano_enrollments WHERE device_id IN (?) AND type IN (?, ?)`,
+		deviceIDs, fleet.NanoEnrollmentTypeUserEnrollmentDevice, fleet.NanoEnrollmentTypeUserEnrollment)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "building IN statement for user enrollments")
+	}
+
+	var ids []string
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &ids, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list nano user enrollment device ids")
+	}
+	return ids, nil
+}
+
 func (ds *Datastore) BatchSetMDMAppleProfiles(ctx context.Context, tmID *uint, profiles []*fleet.MDMAppleConfigProfile) error {
 	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
 		return ds.batchSetMDMAppleProfilesDB(ctx, tx, tmID, profiles)
@@ -1408,6 +1561,11 @@ ON DUPLICATE KEY UPDATE
 		}
 	}
 
+	existingProfsByIdentifier := make(map[string]*fleet.MDMAppleConfigProfile, len(existingProfiles))
+	for _, p := range existingProfiles {
+		existingProfsByIdentifier[p.Identifier] = p
+	}
+
 	// figure out if we need to delete any profiles
 	keepIdents := make([]string, 0, len(incomingIdents))
 	for _, p := range existingProfiles {
@@ -1457,6 +1615,8 @@ ON DUPLICATE KEY UPDATE
 	// between macOS and Windows, but at the time of this
 	// implementation we're under tight time constraints.
 	incomingLabels := []fleet.ConfigurationProfileLabel{}
+	// the rollout of a profile restarts if it is new or its contents changed
+	incomingRollouts := []mdmProfileRolloutSpec{}
 	if len(incomingIdents) > 0 {
 		var newlyInsertedProfs []*fleet.MDMAppleConfigProfile
 		// load current profiles (again) that match the incoming profiles by name to grab their uuids
@@ -1480,10 +1640,17 @@ ON DUPLICATE KEY UPDATE
 				return ctxerr.Wrapf(ctx, err, "profile %q is in the database but was not incoming", newlyInsertedProf.Identifier)
 			}
 
-			for _, label := range incomingProf.Labels {
+			for _, label := range fleet.JoinConfigurationProfileLabels(incomingProf.Labels, incomingProf.LabelsIncludeAny, incomingProf.LabelsExcludeAny) {
 				label.ProfileUUID = newlyInsertedProf.ProfileUUID
 				incomingLabels = append(incomingLabels, label)
 			}
+
+			existingProf := existingProfsByIdentifier[newlyInsertedProf.Identifier]
+			incomingRollouts = append(incomingRollouts, mdmProfileRolloutSpec{
+				profileUUID: newlyInsertedProf.ProfileUUID,
+				settings:    incomingProf.Rollout,
+				restart:     existingProf == nil || !bytes.Equal(existingProf.Mobileconfig, incomingProf.Mobileconfig),
+			})
 		}
 	}
 
@@ -1494,6 +1661,14 @@ ON DUPLICATE KEY UPDATE
 		}
 		return ctxerr.Wrap(ctx, err, "inserting apple profile label associations")
 	}
+
+	// insert/delete the staged rollouts
+	if err := batchSetMDMProfileRolloutsDB(ctx, tx, incomingRollouts); err != nil || strings.HasPrefix(ds.testBatchSetMDMAppleProfilesErr, "rollouts") {
+		if err == nil {
+			err = errors.New(ds.testBatchSetMDMAppleProfilesErr)
+		}
+		return ctxerr.Wrap(ctx, err, "setting apple profile rollouts")
+	}
 	return nil
 }
 
@@ -1598,13 +1773,19 @@ func (ds *Datastore) bulkSetPendingMDMAppleHostProfilesDB(
 		LEFT JOIN host_mdm_apple_profiles hmap
 			ON hmap.profile_uuid = ds.profile_uuid AND hmap.host_uuid = ds.host_uuid
 	WHERE
-		-- profile has been updated
-		( hmap.checksum != ds.checksum ) OR
-		-- profiles in A but not in B
-		( hmap.profile_uuid IS NULL AND hmap.host_uuid IS NULL ) OR
-		-- profiles in A and B but with operation type "remove"
-		( hmap.host_uuid IS NOT NULL AND ( hmap.operation_type = ? OR hmap.operation_type IS NULL ) )
-`, fmt.Sprintf(appleMDMProfilesDesiredStateQuery, "h.uuid IN (?)", "h.uuid IN (?)"))
+		(
+			-- profile has been updated
+			( hmap.checksum != ds.checksum ) OR
+			-- profiles in A but not in B
+			( hmap.profile_uuid IS NULL AND hmap.host_uuid IS NULL ) OR
+			-- profiles in A and B but with operation type "remove"
+			( hmap.host_uuid IS NOT NULL AND ( hmap.operation_type = ? OR hmap.operation_type IS NULL ) )
+		) AND
+		-- profiles in a staged rollout are only installed on the canary hosts
+		-- until the rollout is completed
+		%s
+`, fmt.Sprintf(appleMDMProfilesDesiredStateQuery, "h.uuid IN (?)", "h.uuid IN (?)"),
+		profileRolloutGateCondition("ds.profile_uuid", "ds.host_uuid"))
 
 	// TODO: if a very large number (~65K) of host uuids was matched (via
 	// uuids, teams or profile IDs), could result in too many placeholders (not
@@ -1806,6 +1987,12 @@ var mdmEntityTypeToTable = map[string]string{
 // generateDesiredStateQuery generates a query string that represents the
 // desired state of an Apple entity based on its type (profile or declaration)
 func generateDesiredStateQuery(entityType string) string {
+	// only configuration profiles can be shared with all teams
+	hostTeamCondition := "h.team_id = mae.team_id OR (h.team_id IS NULL AND mae.team_id = 0)"
+	if entityType == "profile" {
+		hostTeamCondition = profileTargetsHostTeamCondition("darwin", "mae", "h")
+	}
+
 	return fmt.Sprintf(`
 	-- non label-based entities
 	SELECT
@@ -1819,11 +2006,12 @@ func generateDesiredStateQuery(entityType string) string {
 	FROM
 		mdm_apple_%[2]ss mae
 			JOIN hosts h
-				ON h.team_id = mae.team_id OR (h.team_id IS NULL AND mae.team_id = 0)
+				ON %[6]s
 			JOIN nano_enrollments ne
 				ON ne.device_id = h.uuid
 	WHERE
-		h.platform = 'darwin' AND
+		h.platform IN ('darwin', 'ios', 'ipados') AND
+		(h.platform = 'darwin' OR mae.identifier NOT IN (%[4]s)) AND
 		ne.enabled = 1 AND
 		ne.type = 'Device' AND
 		NOT EXISTS (
@@ -1835,7 +2023,7 @@ func generateDesiredStateQuery(entityType string) string {
 
 	UNION
 
-	-- label-based entities where the host is a member of all the labels
+	-- label-based entities that target the host
 	SELECT
 		mae.%[1]s_uuid,
 		h.uuid as host_uuid,
@@ -1847,26 +2035,38 @@ func generateDesiredStateQuery(entityType string) string {
 	FROM
 		mdm_apple_%[2]ss mae
 			JOIN hosts h
-				ON h.team_id = mae.team_id OR (h.team_id IS NULL AND mae.team_id = 0)
+				ON %[6]s
 			JOIN nano_enrollments ne
 				ON ne.device_id = h.uuid
 			JOIN mdm_%[2]s_labels mel
 				ON mel.apple_%[1]s_uuid = mae.%[1]s_uuid
 			LEFT OUTER JOIN label_membership lm
 				ON lm.label_id = mel.label_id AND lm.host_id = h.id
+			LEFT OUTER JOIN labels lbl
+				ON lbl.id = mel.label_id
 	WHERE
-		h.platform = 'darwin' AND
+		h.platform IN ('darwin', 'ios', 'ipados') AND
+		(h.platform = 'darwin' OR mae.identifier NOT IN (%[4]s)) AND
 		ne.enabled = 1 AND
 		ne.type = 'Device' AND
 		( %[3]s )
 	GROUP BY
-		mae.%[1]s_uuid, h.uuid, mae.identifier, mae.name, mae.checksum
+		mae.%[1]s_uuid, h.uuid, h.label_updated_at, mae.identifier, mae.name, mae.checksum
 	HAVING
-		count_%[1]s_labels > 0 AND count_host_labels = count_%[1]s_labels
+		%[5]s
 
-	`, entityType, mdmEntityTypeToTable[entityType], "%s")
+	`, entityType, mdmEntityTypeToTable[entityType], "%s", macOSOnlyFleetIdentifiers, profileLabelsTargetingCondition("mel", "h"), hostTeamCondition)
 }
 
+// macOSOnlyFleetIdentifiers is the SQL list of identifiers of the profiles
+// managed by Fleet that only apply to macOS hosts, and must not be delivered
+// to iPhones and iPads.
+var macOSOnlyFleetIdentifiers = fmt.Sprintf("'%s', '%s', '%s'",
+	mobileconfig.FleetFileVaultPayloadIdentifier,
+	mobileconfig.FleetdConfigPayloadIdentifier,
+	mobileconfig.FleetCARootConfigPayloadIdentifier,
+)
+
 // generateEntitiesToInstallQuery is a set difference between:
 //
 //   - Set A (ds), the "desired state", can be obtained from a JOIN between
@@ -1903,21 +2103,36 @@ func generateDesiredStateQuery(entityType string) string {
 // considered for installation. This means that a broken label-based entity,
 // where one of the labels does not exist anymore, will not be considered for
 // installation.
+//
+// Hosts that are pending enrollment approval are excluded from the desired
+// state, so that nothing is installed on them until they are approved.
 func generateEntitiesToInstallQuery(entityType string) string {
+	// only configuration profiles support staged rollouts
+	rolloutGate := "TRUE"
+	if entityType == "profile" {
+		rolloutGate = profileRolloutGateCondition("ds.profile_uuid", "ds.host_uuid")
+	}
+
 	return fmt.Sprintf(`
 	( %[3]s ) as ds
 		LEFT JOIN host_mdm_apple_%[1]ss hmae
 			ON hmae.%[1]s_uuid = ds.%[1]s_uuid AND hmae.host_uuid = ds.host_uuid
 	WHERE
-		-- entity has been updated
-		( hmae.checksum != ds.checksum ) OR
-		-- entity in A but not in B
-		( hmae.%[1]s_uuid IS NULL AND hmae.host_uuid IS NULL ) OR
-		-- entities in A and B but with operation type "remove"
-		( hmae.host_uuid IS NOT NULL AND ( hmae.operation_type = ? OR hmae.operation_type IS NULL ) ) OR
-		-- entities in A and B with operation type "install" and NULL status
-		( hmae.host_uuid IS NOT NULL AND hmae.operation_type = ? AND hmae.status IS NULL )
-`, entityType, mdmEntityTypeToTable[entityType], fmt.Sprintf(generateDesiredStateQuery(entityType), "TRUE", "TRUE"))
+		(
+			-- entity has been updated
+			( hmae.checksum != ds.checksum ) OR
+			-- entity in A but not in B
+			( hmae.%[1]s_uuid IS NULL AND hmae.host_uuid IS NULL ) OR
+			-- entities in A and B but with operation type "remove"
+			( hmae.host_uuid IS NOT NULL AND ( hmae.operation_type = ? OR hmae.operation_type IS NULL ) ) OR
+			-- entities in A and B with operation type "install" and NULL status
+			( hmae.host_uuid IS NOT NULL AND hmae.operation_type = ? AND hmae.status IS NULL )
+		) AND
+		-- entities in a staged rollout are only installed on the canary hosts
+		-- until the rollout is completed
+		%[4]s
+`, entityType, mdmEntityTypeToTable[entityType], fmt.Sprintf(generateDesiredStateQuery(entityType), hostNotPendingApprovalCondition, hostNotPendingApprovalCondition),
+		rolloutGate)
 }
 
 // generateEntitiesToRemoveQuery is a set difference between:
@@ -2459,7 +2674,7 @@ SELECT
   COUNT(id) as count
 FROM
   hosts h
-GROUP BY status, platform, team_id HAVING platform = 'darwin' AND status IN (?, ?, ?, ?) AND %s`
+GROUP BY status, platform, team_id HAVING platform IN ('darwin', 'ios', 'ipados') AND status IN (?, ?, ?, ?) AND %s`
 
 	args = append(args, fleet.MDMDeliveryFailed, fleet.MDMDeliveryPending, fleet.MDMDeliveryVerifying, fleet.MDMDeliveryVerified)
 
@@ -2931,6 +3146,52 @@ WHERE
 	return &dest, nil
 }
 
+func (ds *Datastore) RecordHostFleetdInstall(ctx context.Context, commandUUID string, hostUUID string, retries uint) error {
+	stmt := `INSERT INTO host_mdm_apple_fleetd_installs (command_uuid, host_uuid, retries) VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE command_uuid = VALUES(command_uuid), retries = VALUES(retries)`
+	_, err := ds.writer(ctx).ExecContext(ctx, stmt, commandUUID, hostUUID, retries)
+	return ctxerr.Wrap(ctx, err, "record fleetd install command")
+}
+
+func (ds *Datastore) GetHostMDMFleetdInstall(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+	stmt := `
+SELECT
+    CASE
+        WHEN ncr.status = 'Acknowledged' THEN ?
+        WHEN ncr.status IN ('Error', 'CommandFormatError') THEN ?
+        ELSE ?
+    END AS status,
+    COALESCE(ncr.result, '') AS result,
+    hmafi.command_uuid,
+    hmafi.retries
+FROM
+    host_mdm_apple_fleetd_installs hmafi
+LEFT JOIN nano_command_results ncr ON
+    ncr.command_uuid = hmafi.command_uuid AND ncr.id = hmafi.host_uuid
+WHERE
+    hmafi.host_uuid = ?`
+
+	args := []interface{}{fleet.MDMFleetdInstallInstalled, fleet.MDMFleetdInstallFailed, fleet.MDMFleetdInstallPending, hostUUID}
+
+	var dest fleet.HostMDMFleetdInstall
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &dest, stmt, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("HostMDMFleetdInstall").WithName(hostUUID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host mdm fleetd install")
+	}
+
+	if dest.Status == fleet.MDMFleetdInstallFailed {
+		decoded, err := mdm.DecodeCommandResults(dest.Result)
+		if err != nil {
+			dest.Detail = "fleetd install failed: unable to decode command result"
+		} else {
+			dest.Detail = "fleetd install failed: " + strings.TrimSpace(apple_mdm.FmtErrorChain(decoded.ErrorChain))
+		}
+	}
+	return &dest, nil
+}
+
 func (ds *Datastore) GetMDMAppleBootstrapPackageMeta(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackage, error) {
 	stmt := "SELECT team_id, name, sha256, token, created_at, updated_at FROM mdm_apple_bootstrap_packages WHERE team_id = ?"
 	var bp fleet.MDMAppleBootstrapPackage
@@ -3408,7 +3669,7 @@ func (ds *Datastore) MDMResetEnrollment(ctx context.Context, hostUUID string) er
 			return ctxerr.Wrap(ctx, err, "getting host info from UUID")
 		}
 
-		if host.Platform != "darwin" && host.Platform != "windows" {
+		if !fleet.IsApplePlatform(host.Platform) && host.Platform != "windows" {
 			return ctxerr.Errorf(ctx, "unsupported host platform: %s", host.Platform)
 		}
 
@@ -4109,3 +4370,100 @@ VALUES
 
 	return nil
 }
+
+func (ds *Datastore) GetMDMAppleConfigProfileByHostCommandUUID(ctx context.Context, hostUUID, cmdUUID string) (*fleet.MDMAppleConfigProfile, error) {
+	const stmt = `
+SELECT
+	macp.profile_uuid,
+	macp.profile_id,
+	macp.team_id,
+	macp.name,
+	macp.identifier,
+	macp.mobileconfig,
+	macp.checksum,
+	macp.created_at,
+	macp.uploaded_at
+FROM
+	host_mdm_apple_profiles hmap
+	JOIN mdm_apple_configuration_profiles macp ON macp.profile_uuid = hmap.profile_uuid
+WHERE
+	hmap.host_uuid = ? AND
+	hmap.command_uuid = ?
+`
+	var res fleet.MDMAppleConfigProfile
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &res, stmt, hostUUID, cmdUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("MDMAppleConfigProfile").WithMessage(fmt.Sprintf("host %s, command %s", hostUUID, cmdUUID)))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get mdm apple config profile by command")
+	}
+	return &res, nil
+}
+
+func (ds *Datastore) RecordHostMDMAppleProfileCertificate(ctx context.Context, hostUUID, profileUUID string) error {
+	const stmt = `
+INSERT INTO host_mdm_apple_profile_certificates (host_uuid, profile_uuid, installed_at)
+VALUES (?, ?, CURRENT_TIMESTAMP)
+ON DUPLICATE KEY UPDATE
+	installed_at = VALUES(installed_at),
+	renew_command_uuid = NULL
+`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostUUID, profileUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record host profile certificate")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostMDMAppleProfileCertificatesToRenew(ctx context.Context, installedBefore time.Time, limit int) ([]fleet.HostMDMAppleProfileCertificate, error) {
+	// only the profiles that are still installed are renewed, the ones that
+	// are pending or failed are already being (re)delivered.
+	const stmt = `
+SELECT
+	hmapc.host_uuid,
+	hmapc.profile_uuid,
+	hmap.profile_identifier,
+	hmap.profile_name,
+	hmap.checksum,
+	hmapc.installed_at
+FROM
+	host_mdm_apple_profile_certificates hmapc
+	JOIN host_mdm_apple_profiles hmap
+		ON hmap.host_uuid = hmapc.host_uuid AND hmap.profile_uuid = hmapc.profile_uuid
+WHERE
+	hmapc.installed_at < ? AND
+	hmapc.renew_command_uuid IS NULL AND
+	hmap.operation_type = ? AND
+	hmap.status IN (?, ?)
+ORDER BY hmapc.installed_at ASC
+LIMIT ?
+`
+	var certs []fleet.HostMDMAppleProfileCertificate
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &certs, stmt,
+		installedBefore, fleet.MDMOperationTypeInstall, fleet.MDMDeliveryVerifying, fleet.MDMDeliveryVerified, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host profile certificates to renew")
+	}
+	return certs, nil
+}
+
+func (ds *Datastore) SetCommandForPendingProfileCertificateRenewal(ctx context.Context, certs []fleet.HostMDMAppleProfileCertificate, cmdUUID string) error {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	args := []any{cmdUUID}
+	for _, cert := range certs {
+		sb.WriteString("(?, ?),")
+		args = append(args, cert.HostUUID, cert.ProfileUUID)
+	}
+	stmt := fmt.Sprintf(`
+UPDATE host_mdm_apple_profile_certificates
+SET renew_command_uuid = ?
+WHERE (host_uuid, profile_uuid) IN (%s)
+`, strings.TrimSuffix(sb.String(), ","))
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "set command for pending profile certificate renewal")
+	}
+	return nil
+}
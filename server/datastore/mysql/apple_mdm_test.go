@@ -74,6 +74,12 @@ func TestMDMApple(t *testing.T) {
 		{"MDMAppleSetPendingDeclarationsAs", testMDMAppleSetPendingDeclarationsAs},
 		{"SetOrUpdateMDMAppleDeclaration", testSetOrUpdateMDMAppleDDMDeclaration},
 		{"DEPAssignmentUpdates", testMDMAppleDEPAssignmentUpdates},
+		{"HostMDMAppleProfileCertificates", testHostMDMAppleProfileCertificates},
+		{"ListIOSAndIPadOSToRefetch", testListIOSAndIPadOSToRefetch},
+		{"ListAppleHostsForMDMSoftwareRefetch", testListAppleHostsForMDMSoftwareRefetch},
+		{"ListHostsWithNotNowMDMAppleCommands", testListHostsWithNotNowMDMAppleCommands},
+		{"HostMDMFleetdInstall", testHostMDMFleetdInstall},
+		{"ListNanoMDMUserEnrollmentDeviceIDs", testListNanoMDMUserEnrollmentDeviceIDs},
 	}
 
 	for _, c := range cases {
@@ -5497,3 +5503,436 @@ func createRawAppleCmd(reqType, cmdUUID string) string {
 </dict>
 </plist>`, reqType, cmdUUID)
 }
+
+func testHostMDMAppleProfileCertificates(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	profA, err := ds.NewMDMAppleConfigProfile(ctx, *generateCP("a", "a", 0))
+	require.NoError(t, err)
+	profB, err := ds.NewMDMAppleConfigProfile(ctx, *generateCP("b", "b", 0))
+	require.NoError(t, err)
+
+	installed := func(hostUUID string, prof *fleet.MDMAppleConfigProfile, cmdUUID string, status *fleet.MDMDeliveryStatus) *fleet.MDMAppleBulkUpsertHostProfilePayload {
+		return &fleet.MDMAppleBulkUpsertHostProfilePayload{
+			ProfileUUID:       prof.ProfileUUID,
+			ProfileIdentifier: prof.Identifier,
+			ProfileName:       prof.Name,
+			HostUUID:          hostUUID,
+			CommandUUID:       cmdUUID,
+			OperationType:     fleet.MDMOperationTypeInstall,
+			Status:            status,
+			Checksum:          []byte("csum"),
+		}
+	}
+	err = ds.BulkUpsertMDMAppleHostProfiles(ctx, []*fleet.MDMAppleBulkUpsertHostProfilePayload{
+		installed("host1", profA, "cmd-a1", &fleet.MDMDeliveryVerified),
+		installed("host2", profA, "cmd-a2", &fleet.MDMDeliveryVerifying),
+		installed("host1", profB, "cmd-b1", &fleet.MDMDeliveryPending),
+	})
+	require.NoError(t, err)
+
+	// get the profile installed by a command
+	prof, err := ds.GetMDMAppleConfigProfileByHostCommandUUID(ctx, "host2", "cmd-a2")
+	require.NoError(t, err)
+	require.Equal(t, profA.ProfileUUID, prof.ProfileUUID)
+	require.Equal(t, profA.Mobileconfig, prof.Mobileconfig)
+	_, err = ds.GetMDMAppleConfigProfileByHostCommandUUID(ctx, "host1", "cmd-a2")
+	require.True(t, fleet.IsNotFound(err))
+
+	for _, hostUUID := range []string{"host1", "host2"} {
+		require.NoError(t, ds.RecordHostMDMAppleProfileCertificate(ctx, hostUUID, profA.ProfileUUID))
+	}
+	require.NoError(t, ds.RecordHostMDMAppleProfileCertificate(ctx, "host1", profB.ProfileUUID))
+
+	// nothing to renew yet
+	certs, err := ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Empty(t, certs)
+
+	// make host1's profile A older than host2's
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_mdm_apple_profile_certificates SET installed_at = DATE_SUB(installed_at, INTERVAL 1 DAY) WHERE host_uuid = ? AND profile_uuid = ?`,
+			"host1", profA.ProfileUUID)
+		return err
+	})
+
+	// profile B is pending so it is not renewed
+	certs, err = ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	require.Equal(t, "host1", certs[0].HostUUID)
+	require.Equal(t, "host2", certs[1].HostUUID)
+	for _, cert := range certs {
+		require.Equal(t, profA.ProfileUUID, cert.ProfileUUID)
+		require.Equal(t, profA.Identifier, cert.ProfileIdentifier)
+		require.Equal(t, profA.Name, cert.ProfileName)
+		require.Equal(t, []byte("csum"), cert.Checksum)
+	}
+
+	certs, err = ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(time.Hour), 1)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "host1", certs[0].HostUUID)
+
+	// a renewal in progress is not returned
+	require.NoError(t, ds.SetCommandForPendingProfileCertificateRenewal(ctx, nil, "renew"))
+	require.NoError(t, ds.SetCommandForPendingProfileCertificateRenewal(ctx, certs, "renew"))
+	certs, err = ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "host2", certs[0].HostUUID)
+
+	// installing the profile again completes the renewal
+	require.NoError(t, ds.RecordHostMDMAppleProfileCertificate(ctx, "host1", profA.ProfileUUID))
+	certs, err = ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+
+	// removed profiles are not renewed
+	err = ds.BulkUpsertMDMAppleHostProfiles(ctx, []*fleet.MDMAppleBulkUpsertHostProfilePayload{
+		{
+			ProfileUUID:       profA.ProfileUUID,
+			ProfileIdentifier: profA.Identifier,
+			ProfileName:       profA.Name,
+			HostUUID:          "host2",
+			CommandUUID:       "cmd-rm",
+			OperationType:     fleet.MDMOperationTypeRemove,
+			Status:            &fleet.MDMDeliveryVerifying,
+			Checksum:          []byte("csum"),
+		},
+	})
+	require.NoError(t, err)
+	certs, err = ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "host1", certs[0].HostUUID)
+}
+
+func testListIOSAndIPadOSToRefetch(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	commander, storage := createMDMAppleCommanderAndStorage(t, ds)
+
+	newHost := func(name, platform string, detailUpdatedAt time.Time, enroll bool) *fleet.Host {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:        name,
+			UUID:            name + "-uuid",
+			HardwareSerial:  name + "-serial",
+			Platform:        platform,
+			DetailUpdatedAt: detailUpdatedAt,
+			LabelUpdatedAt:  detailUpdatedAt,
+			PolicyUpdatedAt: detailUpdatedAt,
+			SeenTime:        detailUpdatedAt,
+		})
+		require.NoError(t, err)
+		if enroll {
+			nanoEnroll(t, ds, h, false)
+		}
+		return h
+	}
+
+	now := time.Now()
+	stale := now.Add(-2 * time.Hour)
+	iphone := newHost("iphone", "ios", stale, true)
+	ipad := newHost("ipad", "ipados", now, true)
+	newHost("mac", "darwin", stale, true)
+	newHost("unenrolled-iphone", "ios", stale, false)
+
+	// only the iPhone is stale
+	uuids, err := ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{iphone.UUID}, uuids)
+
+	// requesting a refetch of the iPad includes it
+	require.NoError(t, ds.UpdateHostRefetchRequested(ctx, ipad.ID, true))
+	uuids, err = ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{iphone.UUID, ipad.UUID}, uuids)
+
+	// a pending refetch command excludes the host, other commands don't
+	refetchUUID := fleet.RefetchMDMCommandUUIDPrefix + uuid.NewString()
+	rawCmd := createRawAppleCmd("DeviceInformation", refetchUUID)
+	require.NoError(t, commander.EnqueueCommand(ctx, []string{iphone.UUID}, rawCmd))
+	require.NoError(t, commander.EnqueueCommand(ctx, []string{ipad.UUID}, createRawAppleCmd("ProfileList", uuid.NewString())))
+	uuids, err = ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{ipad.UUID}, uuids)
+
+	// the refetch command is still pending if the device answered NotNow
+	report := func(status string) {
+		err := storage.StoreCommandReport(&mdm.Request{
+			EnrollID: &mdm.EnrollID{ID: iphone.UUID},
+			Context:  ctx,
+		}, &mdm.CommandResults{
+			CommandUUID: refetchUUID,
+			Status:      status,
+			RequestType: "DeviceInformation",
+			Raw:         []byte(rawCmd),
+		})
+		require.NoError(t, err)
+	}
+	report(fleet.MDMAppleStatusNotNow)
+	uuids, err = ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{ipad.UUID}, uuids)
+
+	// once acknowledged, the host is due again until its details are updated
+	report(fleet.MDMAppleStatusAcknowledged)
+	uuids, err = ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{iphone.UUID, ipad.UUID}, uuids)
+}
+
+func testListAppleHostsForMDMSoftwareRefetch(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	commander, _ := createMDMAppleCommanderAndStorage(t, ds)
+
+	newHost := func(name, platform string, withOsquery, enroll bool) *fleet.Host {
+		h := &fleet.Host{
+			Hostname:        name,
+			UUID:            name + "-uuid",
+			HardwareSerial:  name + "-serial",
+			Platform:        platform,
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			PolicyUpdatedAt: time.Now(),
+			SeenTime:        time.Now(),
+		}
+		if withOsquery {
+			h.NodeKey = ptr.String(name + "-node-key")
+			h.OsqueryHostID = ptr.String(name + "-osquery-id")
+		}
+		h, err := ds.NewHost(ctx, h)
+		require.NoError(t, err)
+		if enroll {
+			nanoEnroll(t, ds, h, false)
+		}
+		return h
+	}
+
+	iphone := newHost("iphone", "ios", false, true)
+	mac := newHost("mac", "darwin", false, true)
+	newHost("mac-osquery", "darwin", true, true)
+	newHost("unenrolled-ipad", "ipados", false, false)
+	newHost("windows", "windows", false, false)
+
+	// hosts were never refetched, they are due
+	uuids, err := ds.ListAppleHostsForMDMSoftwareRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{iphone.UUID, mac.UUID}, uuids)
+
+	// software updated recently
+	_, err = ds.UpdateHostSoftware(ctx, mac.ID, []fleet.Software{{Name: "app", Version: "1", Source: fleet.SoftwareSourceMDM}})
+	require.NoError(t, err)
+	uuids, err = ds.ListAppleHostsForMDMSoftwareRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{iphone.UUID}, uuids)
+
+	// a pending software refetch excludes the host, but it does not prevent
+	// refetching its details
+	_, err = ds.writer(ctx).ExecContext(ctx, `UPDATE hosts SET detail_updated_at = ? WHERE id = ?`, time.Now().Add(-2*time.Hour), iphone.ID)
+	require.NoError(t, err)
+	cmdUUID := fleet.RefetchMDMCommandUUIDPrefix + uuid.NewString()
+	require.NoError(t, commander.EnqueueCommand(ctx, []string{iphone.UUID}, createRawAppleCmd("InstalledApplicationList", cmdUUID)))
+	uuids, err = ds.ListAppleHostsForMDMSoftwareRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, uuids)
+	uuids, err = ds.ListIOSAndIPadOSToRefetch(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{iphone.UUID}, uuids)
+}
+
+func testListHostsWithNotNowMDMAppleCommands(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	commander, _ := createMDMAppleCommanderAndStorage(t, ds)
+
+	var hosts []*fleet.Host
+	for _, name := range []string{"iphone", "ipad", "mac"} {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:       name,
+			UUID:           name + "-uuid",
+			HardwareSerial: name + "-serial",
+			Platform:       "ios",
+		})
+		require.NoError(t, err)
+		nanoEnroll(t, ds, h, false)
+		hosts = append(hosts, h)
+	}
+	iphone, ipad, mac := hosts[0], hosts[1], hosts[2]
+
+	setResult := func(host *fleet.Host, cmdUUID, status string, updatedAt time.Time) {
+		ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			_, err := q.ExecContext(ctx, `
+INSERT INTO nano_command_results (id, command_uuid, status, result, updated_at) VALUES (?, ?, ?, '<?xml', ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status), updated_at = VALUES(updated_at)`,
+				host.UUID, cmdUUID, status, updatedAt)
+			return err
+		})
+	}
+	list := func() []string {
+		uuids, err := ds.ListHostsWithNotNowMDMAppleCommands(ctx, "InstallApplication", 15*time.Minute, 24*time.Hour)
+		require.NoError(t, err)
+		return uuids
+	}
+
+	installUUID := uuid.NewString()
+	require.NoError(t, commander.EnqueueCommand(ctx, []string{iphone.UUID, ipad.UUID, mac.UUID}, createRawAppleCmd("InstallApplication", installUUID)))
+	otherUUID := uuid.NewString()
+	require.NoError(t, commander.EnqueueCommand(ctx, []string{mac.UUID}, createRawAppleCmd("InstalledApplicationList", otherUUID)))
+
+	// no response yet
+	require.Empty(t, list())
+
+	// the iPhone responded NotNow a while ago, the iPad just now, and the Mac
+	// responded NotNow to another command
+	setResult(iphone, installUUID, fleet.MDMAppleStatusNotNow, time.Now().Add(-time.Hour))
+	setResult(ipad, installUUID, fleet.MDMAppleStatusNotNow, time.Now())
+	setResult(mac, otherUUID, fleet.MDMAppleStatusNotNow, time.Now().Add(-time.Hour))
+	require.Equal(t, []string{iphone.UUID}, list())
+
+	// the iPhone finally installed the app
+	setResult(iphone, installUUID, fleet.MDMAppleStatusAcknowledged, time.Now().Add(-time.Hour))
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE nano_enrollment_queue SET active = 0 WHERE id = ? AND command_uuid = ?`, iphone.UUID, installUUID)
+		return err
+	})
+	require.Empty(t, list())
+
+	// commands older than the max age are not retried
+	setResult(ipad, installUUID, fleet.MDMAppleStatusNotNow, time.Now().Add(-time.Hour))
+	require.Equal(t, []string{ipad.UUID}, list())
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE nano_enrollment_queue SET created_at = ? WHERE command_uuid = ?`, time.Now().Add(-48*time.Hour), installUUID)
+		return err
+	})
+	require.Empty(t, list())
+}
+
+func testHostMDMFleetdInstall(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	host, err := ds.NewHost(ctx, &fleet.Host{
+		Hostname:      "test-host1-name",
+		OsqueryHostID: ptr.String("1337"),
+		NodeKey:       ptr.String("1337"),
+		UUID:          "host-uuid",
+		Platform:      "darwin",
+	})
+	require.NoError(t, err)
+	nanoEnroll(t, ds, host, false)
+
+	_, err = ds.GetHostMDMFleetdInstall(ctx, host.UUID)
+	require.True(t, fleet.IsNotFound(err))
+
+	for _, cmdUUID := range []string{"cmd-1", "cmd-2"} {
+		ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			_, err := q.ExecContext(ctx, `INSERT INTO nano_commands (command_uuid, request_type, command) VALUES (?, 'InstallEnterpriseApplication', '<?xml')`, cmdUUID)
+			return err
+		})
+	}
+
+	// no result yet, pending
+	err = ds.RecordHostFleetdInstall(ctx, "cmd-1", "host-uuid", 0)
+	require.NoError(t, err)
+	install, err := ds.GetHostMDMFleetdInstall(ctx, "host-uuid")
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMFleetdInstallPending, install.Status)
+	require.Equal(t, "cmd-1", install.CommandUUID)
+	require.Zero(t, install.Retries)
+	require.Empty(t, install.Detail)
+
+	// failed
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO nano_command_results (id, command_uuid, status, result) VALUES ('host-uuid', 'cmd-1', 'Error', ?)`, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>cmd-1</string>
+	<key>ErrorChain</key>
+	<array>
+		<dict>
+			<key>ErrorCode</key>
+			<integer>12</integer>
+			<key>ErrorDomain</key>
+			<string>MCInstallationErrorDomain</string>
+			<key>USEnglishDescription</key>
+			<string>Download failed</string>
+		</dict>
+	</array>
+	<key>Status</key>
+	<string>Error</string>
+	<key>UDID</key>
+	<string>host-uuid</string>
+</dict>
+</plist>`)
+		return err
+	})
+	install, err = ds.GetHostMDMFleetdInstall(ctx, "host-uuid")
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMFleetdInstallFailed, install.Status)
+	require.Equal(t, "fleetd install failed: MCInstallationErrorDomain (12): Download failed", install.Detail)
+
+	// retried and installed
+	err = ds.RecordHostFleetdInstall(ctx, "cmd-2", "host-uuid", 1)
+	require.NoError(t, err)
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO nano_command_results (id, command_uuid, status, result) VALUES ('host-uuid', 'cmd-2', 'Acknowledged', '<?xml')`)
+		return err
+	})
+	install, err = ds.GetHostMDMFleetdInstall(ctx, "host-uuid")
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMFleetdInstallInstalled, install.Status)
+	require.Equal(t, "cmd-2", install.CommandUUID)
+	require.EqualValues(t, 1, install.Retries)
+	require.Empty(t, install.Detail)
+}
+
+func testListNanoMDMUserEnrollmentDeviceIDs(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	ids, err := ds.ListNanoMDMUserEnrollmentDeviceIDs(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		h := test.NewHost(t, ds, fmt.Sprintf("h%d", i), "", fmt.Sprintf("key%d", i), fmt.Sprintf("uuid%d", i), time.Now())
+		nanoEnroll(t, ds, h, i == 0)
+		hosts = append(hosts, h)
+	}
+
+	// none of the hosts is enrolled via User Enrollment
+	ids, err = ds.ListNanoMDMUserEnrollmentDeviceIDs(ctx, []string{hosts[0].UUID, hosts[1].UUID, hosts[2].UUID, "no-such-host"})
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	// make hosts[1] a User Enrollment, with both the device and the user channels
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		if _, err := q.ExecContext(ctx, `UPDATE nano_enrollments SET type = ? WHERE id = ?`,
+			fleet.NanoEnrollmentTypeUserEnrollmentDevice, hosts[1].UUID); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `
+INSERT INTO nano_enrollments
+	(id, device_id, user_id, type, topic, push_magic, token_hex)
+VALUES
+	(?, ?, NULL, ?, 'topic', 'magic', 'token')`,
+			hosts[1].UUID+":user", hosts[1].UUID, fleet.NanoEnrollmentTypeUserEnrollment)
+		return err
+	})
+
+	ids, err = ds.ListNanoMDMUserEnrollmentDeviceIDs(ctx, []string{hosts[0].UUID, hosts[1].UUID, hosts[2].UUID})
+	require.NoError(t, err)
+	require.Equal(t, []string{hosts[1].UUID}, ids)
+
+	ids, err = ds.ListNanoMDMUserEnrollmentDeviceIDs(ctx, []string{hosts[0].UUID, hosts[2].UUID})
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	enrollment, err := ds.GetNanoMDMEnrollment(ctx, hosts[1].UUID)
+	require.NoError(t, err)
+	require.True(t, enrollment.IsUserEnrollment())
+	enrollment, err = ds.GetNanoMDMEnrollment(ctx, hosts[0].UUID)
+	require.NoError(t, err)
+	require.False(t, enrollment.IsUserEnrollment())
+}
@@ -12,7 +12,7 @@ import (
 )
 
 func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
-	args := []any{camp.QueryID, camp.Status, camp.UserID}
+	args := []any{camp.QueryID, camp.Status, camp.UserID, camp.PostProcessing}
 
 	// for tests, we sometimes provide specific timestamps for CreatedAt, honor
 	// those if provided.
@@ -27,10 +27,11 @@ func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *flee
 		INSERT INTO distributed_query_campaigns (
 			query_id,
 			status,
-			user_id
+			user_id,
+			post_processing
 			%s
 		)
-		VALUES(?,?,?%s)
+		VALUES(?,?,?,?%s)
 	`, createdAtField, createdAtPlaceholder)
 	result, err := ds.writer(ctx).ExecContext(ctx, sqlStatement, args...)
 	if err != nil {
@@ -99,6 +100,7 @@ func (ds *Datastore) DistributedQueryCampaignTargetIDs(ctx context.Context, id u
 	hostIDs := []uint{}
 	labelIDs := []uint{}
 	teamIDs := []uint{}
+	hostGroupIDs := []uint{}
 	for _, target := range targets {
 		switch target.Type {
 		case fleet.TargetHost:
@@ -107,12 +109,14 @@ func (ds *Datastore) DistributedQueryCampaignTargetIDs(ctx context.Context, id u
 			labelIDs = append(labelIDs, target.TargetID)
 		case fleet.TargetTeam:
 			teamIDs = append(teamIDs, target.TargetID)
+		case fleet.TargetHostGroup:
+			hostGroupIDs = append(hostGroupIDs, target.TargetID)
 		default:
 			return nil, ctxerr.Errorf(ctx, "invalid target type: %d", target.Type)
 		}
 	}
 
-	return &fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs, TeamIDs: teamIDs}, nil
+	return &fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs, TeamIDs: teamIDs, HostGroupIDs: hostGroupIDs}, nil
 }
 
 func (ds *Datastore) NewDistributedQueryCampaignTarget(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
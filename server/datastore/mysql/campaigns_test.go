@@ -25,6 +25,7 @@ func TestCampaigns(t *testing.T) {
 		{"CleanupDistributedQuery", testCampaignsCleanupDistributedQuery},
 		{"SaveDistributedQuery", testCampaignsSaveDistributedQuery},
 		{"CompletedCampaigns", testCompletedCampaigns},
+		{"PostProcessing", testCampaignsPostProcessing},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -254,3 +255,43 @@ func testCompletedCampaigns(t *testing.T, ds *Datastore) {
 	assert.Equal(t, complete, result)
 
 }
+
+func testCampaignsPostProcessing(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, nil, "test", "select * from time", user.ID, false)
+
+	// without post-processing
+	campaign := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+	retrieved, err := ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.Nil(t, retrieved.PostProcessing)
+
+	// with post-processing
+	pp := &fleet.QueryPostProcessing{Function: fleet.QueryPostProcessingGroupBy, Column: "name"}
+	campaign, err = ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID:        query.ID,
+		Status:         fleet.QueryWaiting,
+		UserID:         user.ID,
+		PostProcessing: pp,
+	})
+	require.NoError(t, err)
+	retrieved, err = ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.Equal(t, pp, retrieved.PostProcessing)
+
+	// the post-processing is kept when the campaign is saved
+	retrieved.Status = fleet.QueryComplete
+	require.NoError(t, ds.SaveDistributedQueryCampaign(ctx, retrieved))
+	campaigns, err := ds.DistributedQueryCampaignsForQuery(ctx, query.ID)
+	require.NoError(t, err)
+	require.Len(t, campaigns, 2)
+	for _, c := range campaigns {
+		if c.ID == campaign.ID {
+			require.Equal(t, fleet.QueryComplete, c.Status)
+			require.Equal(t, pp, c.PostProcessing)
+		} else {
+			require.Nil(t, c.PostProcessing)
+		}
+	}
+}
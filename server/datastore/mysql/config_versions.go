@@ -0,0 +1,303 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // used only to detect changes, not for security
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm"
+	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
+	"github.com/jmoiron/sqlx"
+)
+
+const configVersionSelectColumns = `
+	id,
+	team_id,
+	author_id,
+	author_name,
+	created_at
+`
+
+// configVersionProfile is the stored representation of a
+// fleet.ConfigVersionProfile, which includes the contents of the profile.
+type configVersionProfile struct {
+	Name             string   `json:"name"`
+	Platform         string   `json:"platform"`
+	Labels           []string `json:"labels,omitempty"`
+	LabelsIncludeAny []string `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []string `json:"labels_exclude_any,omitempty"`
+	Contents         []byte   `json:"contents"`
+
+	Rollout *fleet.MDMProfileRolloutSettings `json:"rollout,omitempty"`
+}
+
+func (ds *Datastore) NewConfigVersion(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+	var versionID uint
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var config json.RawMessage
+		if teamID == nil {
+			if err := sqlx.GetContext(ctx, tx, &config, `SELECT json_value FROM app_config_json LIMIT 1`); err != nil {
+				return ctxerr.Wrap(ctx, err, "select app config")
+			}
+		} else {
+			if err := sqlx.GetContext(ctx, tx, &config, `SELECT COALESCE(config, '{}') FROM teams WHERE id = ?`, *teamID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ctxerr.Wrap(ctx, notFound("Team").WithID(*teamID))
+				}
+				return ctxerr.Wrap(ctx, err, "select team config")
+			}
+		}
+
+		profiles, err := listConfigVersionProfilesDB(ctx, tx, teamID)
+		if err != nil {
+			return err
+		}
+		profilesJSON, err := json.Marshal(profiles)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal profiles")
+		}
+
+		h := md5.New() //nolint:gosec // used only to detect changes, not for security
+		_, _ = h.Write(config)
+		_, _ = h.Write(profilesJSON)
+		checksum := h.Sum(nil)
+
+		var latest struct {
+			ID       uint   `db:"id"`
+			Checksum []byte `db:"checksum"`
+		}
+		err = sqlx.GetContext(ctx, tx, &latest, `
+			SELECT id, checksum
+			FROM config_versions
+			WHERE team_id <=> ?
+			ORDER BY id DESC
+			LIMIT 1`, teamID)
+		switch {
+		case err == nil && bytes.Equal(latest.Checksum, checksum):
+			versionID = latest.ID
+			return nil
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return ctxerr.Wrap(ctx, err, "select latest config version")
+		}
+
+		var authorID *uint
+		var authorName string
+		if author != nil {
+			authorID = &author.ID
+			authorName = author.Name
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO config_versions (team_id, author_id, author_name, config, profiles, checksum)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			teamID, authorID, authorName, config, profilesJSON, checksum)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert config version")
+		}
+		id, _ := res.LastInsertId() // cannot fail with the mysql driver
+		versionID = uint(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ds.configVersionByID(ctx, ds.writer(ctx), versionID)
+}
+
+// listConfigVersionProfilesDB returns the custom configuration profiles of
+// the team (or "No team" if teamID is nil) with their contents and labels,
+// sorted by name. Fleet-reserved profiles are not included, as they are
+// managed by Fleet based on the configuration.
+func listConfigVersionProfilesDB(ctx context.Context, q sqlx.QueryerContext, teamID *uint) ([]configVersionProfile, error) {
+	const stmt = `
+	SELECT name, platform, contents, apple_uuid, windows_uuid FROM (
+		SELECT
+			name,
+			'darwin' AS platform,
+			mobileconfig AS contents,
+			profile_uuid AS apple_uuid,
+			NULL AS windows_uuid
+		FROM mdm_apple_configuration_profiles
+		WHERE team_id = ? AND identifier NOT IN (?)
+
+		UNION ALL
+
+		SELECT
+			name,
+			'windows' AS platform,
+			syncml AS contents,
+			NULL AS apple_uuid,
+			profile_uuid AS windows_uuid
+		FROM mdm_windows_configuration_profiles
+		WHERE team_id = ? AND name NOT IN (?)
+
+		UNION ALL
+
+		SELECT
+			name,
+			'darwin' AS platform,
+			raw_json AS contents,
+			NULL AS apple_uuid,
+			NULL AS windows_uuid
+		FROM mdm_apple_declarations
+		WHERE team_id = ? AND name NOT IN (?)
+	) AS profiles
+	ORDER BY name`
+
+	var globalOrTeamID uint
+	if teamID != nil {
+		globalOrTeamID = *teamID
+	}
+
+	fleetIdentifiers := make([]string, 0, len(mobileconfig.FleetPayloadIdentifiers()))
+	for k := range mobileconfig.FleetPayloadIdentifiers() {
+		fleetIdentifiers = append(fleetIdentifiers, k)
+	}
+	fleetNames := make([]string, 0, len(mdm.FleetReservedProfileNames()))
+	for k := range mdm.FleetReservedProfileNames() {
+		fleetNames = append(fleetNames, k)
+	}
+
+	query, args, err := sqlx.In(stmt, globalOrTeamID, fleetIdentifiers, globalOrTeamID, fleetNames, globalOrTeamID, fleetNames)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "sqlx.In list config version profiles")
+	}
+	var rows []struct {
+		Name        string  `db:"name"`
+		Platform    string  `db:"platform"`
+		Contents    []byte  `db:"contents"`
+		AppleUUID   *string `db:"apple_uuid"`
+		WindowsUUID *string `db:"windows_uuid"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, query, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select config version profiles")
+	}
+
+	var labelRows []struct {
+		AppleUUID   *string `db:"apple_profile_uuid"`
+		WindowsUUID *string `db:"windows_profile_uuid"`
+		fleet.ConfigurationProfileLabel
+	}
+	const labelsStmt = `
+	SELECT mcpl.apple_profile_uuid, mcpl.windows_profile_uuid, mcpl.label_name, mcpl.exclude, mcpl.require_all
+	FROM mdm_configuration_profile_labels mcpl
+	LEFT JOIN mdm_apple_configuration_profiles macp ON macp.profile_uuid = mcpl.apple_profile_uuid
+	LEFT JOIN mdm_windows_configuration_profiles mwcp ON mwcp.profile_uuid = mcpl.windows_profile_uuid
+	WHERE macp.team_id = ? OR mwcp.team_id = ?
+	ORDER BY mcpl.label_name`
+	if err := sqlx.SelectContext(ctx, q, &labelRows, labelsStmt, globalOrTeamID, globalOrTeamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select config version profile labels")
+	}
+	labelsByUUID := make(map[string][]fleet.ConfigurationProfileLabel)
+	for _, l := range labelRows {
+		switch {
+		case l.AppleUUID != nil:
+			labelsByUUID[*l.AppleUUID] = append(labelsByUUID[*l.AppleUUID], l.ConfigurationProfileLabel)
+		case l.WindowsUUID != nil:
+			labelsByUUID[*l.WindowsUUID] = append(labelsByUUID[*l.WindowsUUID], l.ConfigurationProfileLabel)
+		}
+	}
+
+	var rolloutRows []struct {
+		ProfileUUID string `db:"profile_uuid"`
+		fleet.MDMProfileRolloutSettings
+	}
+	const rolloutsStmt = `
+	SELECT mpr.profile_uuid, mpr.canary_percentage, mpr.failure_threshold_percentage, mpr.timeout_minutes
+	FROM mdm_profile_rollouts mpr
+	LEFT JOIN mdm_apple_configuration_profiles macp ON macp.profile_uuid = mpr.profile_uuid
+	LEFT JOIN mdm_windows_configuration_profiles mwcp ON mwcp.profile_uuid = mpr.profile_uuid
+	WHERE macp.team_id = ? OR mwcp.team_id = ?`
+	if err := sqlx.SelectContext(ctx, q, &rolloutRows, rolloutsStmt, globalOrTeamID, globalOrTeamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select config version profile rollouts")
+	}
+	rolloutsByUUID := make(map[string]*fleet.MDMProfileRolloutSettings, len(rolloutRows))
+	for _, r := range rolloutRows {
+		settings := r.MDMProfileRolloutSettings
+		rolloutsByUUID[r.ProfileUUID] = &settings
+	}
+
+	profiles := make([]configVersionProfile, 0, len(rows))
+	for _, r := range rows {
+		p := configVersionProfile{Name: r.Name, Platform: r.Platform, Contents: r.Contents}
+		var labels []fleet.ConfigurationProfileLabel
+		switch {
+		case r.AppleUUID != nil:
+			labels = labelsByUUID[*r.AppleUUID]
+			p.Rollout = rolloutsByUUID[*r.AppleUUID]
+		case r.WindowsUUID != nil:
+			labels = labelsByUUID[*r.WindowsUUID]
+			p.Rollout = rolloutsByUUID[*r.WindowsUUID]
+		}
+		for _, l := range labels {
+			switch {
+			case l.Exclude:
+				p.LabelsExcludeAny = append(p.LabelsExcludeAny, l.LabelName)
+			case l.RequireAll:
+				p.Labels = append(p.Labels, l.LabelName)
+			default:
+				p.LabelsIncludeAny = append(p.LabelsIncludeAny, l.LabelName)
+			}
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func (ds *Datastore) ConfigVersion(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+	return ds.configVersionByID(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) configVersionByID(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.ConfigVersion, error) {
+	stmt := `SELECT ` + configVersionSelectColumns + `, config, profiles FROM config_versions WHERE id = ?`
+
+	var row struct {
+		fleet.ConfigVersion
+		Profiles []byte `db:"profiles"`
+	}
+	if err := sqlx.GetContext(ctx, q, &row, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("ConfigVersion").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "select config version")
+	}
+
+	var profiles []configVersionProfile
+	if err := json.Unmarshal(row.Profiles, &profiles); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal config version profiles")
+	}
+	version := row.ConfigVersion
+	version.Profiles = make([]fleet.ConfigVersionProfile, 0, len(profiles))
+	for _, p := range profiles {
+		version.Profiles = append(version.Profiles, fleet.ConfigVersionProfile(p))
+	}
+	return &version, nil
+}
+
+func (ds *Datastore) ListConfigVersions(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.ConfigVersion, *fleet.PaginationMetadata, error) {
+	// versions are always listed from the most recent one
+	opt.OrderKey = "id"
+	opt.OrderDirection = fleet.OrderDescending
+
+	stmt := `SELECT ` + configVersionSelectColumns + ` FROM config_versions WHERE team_id <=> ?`
+	stmt, args := appendListOptionsWithCursorToSQL(stmt, []any{teamID}, &opt)
+
+	versions := []*fleet.ConfigVersion{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &versions, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list config versions")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opt.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opt.Page > 0}
+		if len(versions) > int(opt.PerPage) {
+			meta.HasNextResults = true
+			versions = versions[:len(versions)-1]
+		}
+	}
+	return versions, meta, nil
+}
@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigVersions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Global", testConfigVersionsGlobal},
+		{"Team", testConfigVersionsTeam},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testConfigVersionsGlobal(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user := &fleet.User{Name: "Admin", Email: "admin@example.com", Password: []byte("p"), GlobalRole: ptr.String(fleet.RoleAdmin)}
+	user, err := ds.NewUser(ctx, user)
+	require.NoError(t, err)
+
+	versions, _, err := ds.ListConfigVersions(ctx, nil, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	v1, err := ds.NewConfigVersion(ctx, nil, user)
+	require.NoError(t, err)
+	require.NotZero(t, v1.ID)
+	require.Nil(t, v1.TeamID)
+	require.Equal(t, user.ID, *v1.AuthorID)
+	require.Equal(t, "Admin", v1.AuthorName)
+	require.Empty(t, v1.Profiles)
+	var cfg fleet.AppConfig
+	require.NoError(t, json.Unmarshal(v1.Config, &cfg))
+
+	// nothing changed, the same version is returned
+	v, err := ds.NewConfigVersion(ctx, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, v1.ID, v.ID)
+
+	// change the config
+	cfg.OrgInfo.OrgName = "Changed"
+	require.NoError(t, ds.SaveAppConfig(ctx, &cfg))
+	v2, err := ds.NewConfigVersion(ctx, nil, nil)
+	require.NoError(t, err)
+	require.Greater(t, v2.ID, v1.ID)
+	require.Nil(t, v2.AuthorID)
+	require.NoError(t, json.Unmarshal(v2.Config, &cfg))
+	require.Equal(t, "Changed", cfg.OrgInfo.OrgName)
+
+	// add "No team" profiles
+	lbl, err := ds.NewLabel(ctx, &fleet.Label{Name: "label1", Query: "select 1"})
+	require.NoError(t, err)
+	err = ds.BatchSetMDMProfiles(ctx, nil,
+		[]*fleet.MDMAppleConfigProfile{configProfileForTest(t, "A", "A", "a", lbl)},
+		[]*fleet.MDMWindowsConfigProfile{windowsConfigProfileForTest(t, "B", "./Device/Vendor/MSFT/B")},
+		[]*fleet.MDMAppleDeclaration{declForTest("C", "C", "c")},
+	)
+	require.NoError(t, err)
+	v3, err := ds.NewConfigVersion(ctx, nil, user)
+	require.NoError(t, err)
+	require.Greater(t, v3.ID, v2.ID)
+	require.Len(t, v3.Profiles, 3)
+	require.Equal(t, "A", v3.Profiles[0].Name)
+	require.Equal(t, "darwin", v3.Profiles[0].Platform)
+	require.Equal(t, []string{"label1"}, v3.Profiles[0].Labels)
+	require.Contains(t, string(v3.Profiles[0].Contents), "<plist")
+	require.Equal(t, "B", v3.Profiles[1].Name)
+	require.Equal(t, "windows", v3.Profiles[1].Platform)
+	require.Empty(t, v3.Profiles[1].Labels)
+	require.Contains(t, string(v3.Profiles[1].Contents), "./Device/Vendor/MSFT/B")
+	require.Equal(t, "C", v3.Profiles[2].Name)
+	require.Equal(t, "darwin", v3.Profiles[2].Platform)
+
+	// team profiles are not part of the global versions
+	tm, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	err = ds.BatchSetMDMProfiles(ctx, &tm.ID, []*fleet.MDMAppleConfigProfile{configProfileForTest(t, "D", "D", "d")}, nil, nil)
+	require.NoError(t, err)
+	v, err = ds.NewConfigVersion(ctx, nil, user)
+	require.NoError(t, err)
+	require.Equal(t, v3.ID, v.ID)
+
+	versions, meta, err := ds.ListConfigVersions(ctx, nil, fleet.ListOptions{PerPage: 2, IncludeMetadata: true})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, v3.ID, versions[0].ID)
+	require.Equal(t, v2.ID, versions[1].ID)
+	require.Nil(t, versions[0].Config)
+	require.Nil(t, versions[0].Profiles)
+	require.True(t, meta.HasNextResults)
+	require.False(t, meta.HasPreviousResults)
+
+	versions, meta, err = ds.ListConfigVersions(ctx, nil, fleet.ListOptions{Page: 1, PerPage: 2, IncludeMetadata: true})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.Equal(t, v1.ID, versions[0].ID)
+	require.False(t, meta.HasNextResults)
+	require.True(t, meta.HasPreviousResults)
+
+	v, err = ds.ConfigVersion(ctx, v2.ID)
+	require.NoError(t, err)
+	require.JSONEq(t, string(v2.Config), string(v.Config))
+
+	_, err = ds.ConfigVersion(ctx, v3.ID+100)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testConfigVersionsTeam(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	_, err := ds.NewConfigVersion(ctx, ptr.Uint(123), nil)
+	require.True(t, fleet.IsNotFound(err))
+
+	tm1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	tm2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	v1, err := ds.NewConfigVersion(ctx, &tm1.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, tm1.ID, *v1.TeamID)
+
+	tm1.Config.HostExpirySettings = fleet.HostExpirySettings{HostExpiryEnabled: true, HostExpiryWindow: 10}
+	_, err = ds.SaveTeam(ctx, tm1)
+	require.NoError(t, err)
+	err = ds.BatchSetMDMProfiles(ctx, &tm1.ID, []*fleet.MDMAppleConfigProfile{configProfileForTest(t, "A", "A", "a")}, nil, nil)
+	require.NoError(t, err)
+	v2, err := ds.NewConfigVersion(ctx, &tm1.ID, nil)
+	require.NoError(t, err)
+	require.Greater(t, v2.ID, v1.ID)
+	require.Len(t, v2.Profiles, 1)
+	var cfg fleet.TeamConfig
+	require.NoError(t, json.Unmarshal(v2.Config, &cfg))
+	require.Equal(t, 10, cfg.HostExpirySettings.HostExpiryWindow)
+
+	v3, err := ds.NewConfigVersion(ctx, &tm2.ID, nil)
+	require.NoError(t, err)
+	require.Greater(t, v3.ID, v2.ID)
+	require.Empty(t, v3.Profiles)
+
+	versions, _, err := ds.ListConfigVersions(ctx, &tm1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, v2.ID, versions[0].ID)
+	require.Equal(t, v1.ID, versions[1].ID)
+
+	versions, _, err = ds.ListConfigVersions(ctx, nil, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	// deleting the team deletes its versions
+	require.NoError(t, ds.DeleteTeam(ctx, tm1.ID))
+	_, err = ds.ConfigVersion(ctx, v1.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
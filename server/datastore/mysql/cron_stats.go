@@ -15,7 +15,7 @@ func (ds *Datastore) GetLatestCronStats(ctx context.Context, name string) ([]fle
 	stmt := `
 (
 	SELECT
-		id, name, instance, stats_type, status, created_at, updated_at
+		id, name, instance, stats_type, status, created_at, updated_at, errors
 	FROM
 		cron_stats
 	WHERE
@@ -28,7 +28,7 @@ func (ds *Datastore) GetLatestCronStats(ctx context.Context, name string) ([]fle
 UNION
 (
 	SELECT
-		id, name, instance, stats_type, status, created_at, updated_at
+		id, name, instance, stats_type, status, created_at, updated_at, errors
 	FROM
 		cron_stats
 	WHERE
@@ -63,10 +63,10 @@ func (ds *Datastore) InsertCronStats(ctx context.Context, statsType fleet.CronSt
 	return int(id), nil
 }
 
-func (ds *Datastore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus) error {
-	stmt := `UPDATE cron_stats SET status = ? WHERE id = ?`
+func (ds *Datastore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error {
+	stmt := `UPDATE cron_stats SET status = ?, errors = ? WHERE id = ?`
 
-	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, status, id); err != nil {
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, status, cronErrors, id); err != nil {
 		return ctxerr.Wrap(ctx, err, "update cron stats")
 	}
 
@@ -102,3 +102,59 @@ func (ds *Datastore) CleanupCronStats(ctx context.Context) error {
 		return nil
 	})
 }
+
+// ListLatestCronStats returns, for each cron schedule and stats type, the most recent pending run
+// and the most recent completed run, if any.
+func (ds *Datastore) ListLatestCronStats(ctx context.Context) ([]fleet.CronStats, error) {
+	stmt := `
+SELECT
+	cs.id, cs.name, cs.instance, cs.stats_type, cs.status, cs.created_at, cs.updated_at, cs.errors
+FROM
+	cron_stats cs
+	JOIN (
+		SELECT
+			MAX(id) AS id
+		FROM
+			cron_stats
+		WHERE
+			status = 'pending' OR status = 'completed'
+		GROUP BY
+			name, stats_type, status
+	) latest ON latest.id = cs.id
+ORDER BY
+	cs.name, cs.created_at`
+
+	var res []fleet.CronStats
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &res, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select latest cron stats")
+	}
+	return res, nil
+}
+
+func (ds *Datastore) IsCronSchedulePaused(ctx context.Context, name string) (bool, error) {
+	var paused bool
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &paused, `SELECT EXISTS(SELECT 1 FROM cron_schedule_pauses WHERE name = ?)`, name); err != nil {
+		return false, ctxerr.Wrap(ctx, err, "check cron schedule paused")
+	}
+	return paused, nil
+}
+
+func (ds *Datastore) SetCronSchedulePaused(ctx context.Context, name string, paused bool) error {
+	stmt := `DELETE FROM cron_schedule_pauses WHERE name = ?`
+	if paused {
+		stmt = `INSERT IGNORE INTO cron_schedule_pauses (name) VALUES (?)`
+	}
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, name); err != nil {
+		return ctxerr.Wrap(ctx, err, "set cron schedule paused")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListPausedCronSchedules(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &names, `SELECT name FROM cron_schedule_pauses ORDER BY name`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list paused cron schedules")
+	}
+	return names, nil
+}
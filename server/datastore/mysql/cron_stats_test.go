@@ -28,7 +28,7 @@ func TestInsertUpdateCronStats(t *testing.T) {
 	require.Equal(t, fleet.CronStatsTypeScheduled, res[0].StatsType)
 	require.Equal(t, fleet.CronStatsStatusPending, res[0].Status)
 
-	err = ds.UpdateCronStats(ctx, id, fleet.CronStatsStatusCompleted)
+	err = ds.UpdateCronStats(ctx, id, fleet.CronStatsStatusCompleted, fleet.CronScheduleErrors{"job_1": "boom"})
 	require.NoError(t, err)
 
 	res, err = ds.GetLatestCronStats(ctx, scheduleName)
@@ -37,6 +37,7 @@ func TestInsertUpdateCronStats(t *testing.T) {
 	require.Equal(t, id, res[0].ID)
 	require.Equal(t, fleet.CronStatsTypeScheduled, res[0].StatsType)
 	require.Equal(t, fleet.CronStatsStatusCompleted, res[0].Status)
+	require.Equal(t, fleet.CronScheduleErrors{"job_1": "boom"}, res[0].Errors)
 }
 
 func TestGetLatestCronStats(t *testing.T) {
@@ -101,6 +102,77 @@ func TestGetLatestCronStats(t *testing.T) {
 	require.Equal(t, then.Add(2*time.Hour), res[1].CreatedAt)
 }
 
+func TestListLatestCronStats(t *testing.T) {
+	ctx := context.Background()
+	ds := CreateMySQLDS(t)
+
+	insertTestCS := func(name string, statsType fleet.CronStatsType, status fleet.CronStatsStatus) int {
+		id, err := ds.InsertCronStats(ctx, statsType, name, "test_instance", status)
+		require.NoError(t, err)
+		return id
+	}
+
+	res, err := ds.ListLatestCronStats(ctx)
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	insertTestCS("sched_1", fleet.CronStatsTypeScheduled, fleet.CronStatsStatusCompleted)
+	completed1 := insertTestCS("sched_1", fleet.CronStatsTypeScheduled, fleet.CronStatsStatusCompleted)
+	pending1 := insertTestCS("sched_1", fleet.CronStatsTypeScheduled, fleet.CronStatsStatusPending)
+	triggered1 := insertTestCS("sched_1", fleet.CronStatsTypeTriggered, fleet.CronStatsStatusCompleted)
+	insertTestCS("sched_1", fleet.CronStatsTypeScheduled, fleet.CronStatsStatusExpired) // expired status shouldn't be returned
+	completed2 := insertTestCS("sched_2", fleet.CronStatsTypeScheduled, fleet.CronStatsStatusPending)
+	require.NoError(t, ds.UpdateCronStats(ctx, completed2, fleet.CronStatsStatusCompleted, fleet.CronScheduleErrors{"job": "boom"}))
+
+	res, err = ds.ListLatestCronStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, res, 4)
+	byID := make(map[int]fleet.CronStats, len(res))
+	for _, s := range res {
+		byID[s.ID] = s
+	}
+	require.Contains(t, byID, completed1)
+	require.Contains(t, byID, pending1)
+	require.Contains(t, byID, triggered1)
+	require.Contains(t, byID, completed2)
+	require.Equal(t, fleet.CronScheduleErrors{"job": "boom"}, byID[completed2].Errors)
+	require.Nil(t, byID[completed1].Errors)
+}
+
+func TestCronSchedulePauses(t *testing.T) {
+	ctx := context.Background()
+	ds := CreateMySQLDS(t)
+
+	paused, err := ds.IsCronSchedulePaused(ctx, "sched_1")
+	require.NoError(t, err)
+	require.False(t, paused)
+	names, err := ds.ListPausedCronSchedules(ctx)
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	// pausing is idempotent
+	require.NoError(t, ds.SetCronSchedulePaused(ctx, "sched_1", true))
+	require.NoError(t, ds.SetCronSchedulePaused(ctx, "sched_1", true))
+	require.NoError(t, ds.SetCronSchedulePaused(ctx, "sched_2", true))
+
+	paused, err = ds.IsCronSchedulePaused(ctx, "sched_1")
+	require.NoError(t, err)
+	require.True(t, paused)
+	names, err = ds.ListPausedCronSchedules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sched_1", "sched_2"}, names)
+
+	// resuming is idempotent
+	require.NoError(t, ds.SetCronSchedulePaused(ctx, "sched_1", false))
+	require.NoError(t, ds.SetCronSchedulePaused(ctx, "sched_1", false))
+	paused, err = ds.IsCronSchedulePaused(ctx, "sched_1")
+	require.NoError(t, err)
+	require.False(t, paused)
+	names, err = ds.ListPausedCronSchedules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sched_2"}, names)
+}
+
 func TestCleanupCronStats(t *testing.T) {
 	ctx := context.Background()
 	ds := CreateMySQLDS(t)
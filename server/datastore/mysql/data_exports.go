@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const dataExportSelectColumns = `
+	id,
+	user_id,
+	resource,
+	format,
+	status,
+	filename,
+	size,
+	error_message,
+	options,
+	created_at,
+	completed_at
+`
+
+func (ds *Datastore) NewDataExport(ctx context.Context, export *fleet.DataExport) (*fleet.DataExport, error) {
+	const stmt = `
+		INSERT INTO data_exports (user_id, resource, format, status, filename, options)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		export.UserID, export.Resource, export.Format, fleet.DataExportStatusPending, export.Filename, export.Options)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "inserting data export")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return dataExportDB(ctx, ds.writer(ctx), uint(id))
+}
+
+func (ds *Datastore) DataExport(ctx context.Context, id uint) (*fleet.DataExport, error) {
+	return dataExportDB(ctx, ds.reader(ctx), id)
+}
+
+func dataExportDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.DataExport, error) {
+	stmt := `SELECT ` + dataExportSelectColumns + ` FROM data_exports WHERE id = ?`
+
+	var export fleet.DataExport
+	if err := sqlx.GetContext(ctx, q, &export, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("DataExport").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting data export")
+	}
+	return &export, nil
+}
+
+func (ds *Datastore) DataExportContent(ctx context.Context, id uint) ([]byte, error) {
+	var data []byte
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &data, `SELECT data FROM data_exports WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("DataExport").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting data export content")
+	}
+	return data, nil
+}
+
+func (ds *Datastore) ListPendingDataExports(ctx context.Context) ([]*fleet.DataExport, error) {
+	stmt := `SELECT ` + dataExportSelectColumns + ` FROM data_exports WHERE status = ? ORDER BY created_at, id`
+
+	var exports []*fleet.DataExport
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &exports, stmt, fleet.DataExportStatusPending); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing pending data exports")
+	}
+	return exports, nil
+}
+
+func (ds *Datastore) CompleteDataExport(ctx context.Context, id uint, data []byte, exportErr string) error {
+	status := fleet.DataExportStatusCompleted
+	var errMsg *string
+	if exportErr != "" {
+		status = fleet.DataExportStatusFailed
+		errMsg = &exportErr
+		data = nil
+	}
+
+	const stmt = `
+		UPDATE data_exports
+		SET status = ?, data = ?, size = ?, error_message = ?, completed_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, status, data, len(data), errMsg, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "completing data export")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("DataExport").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupDataExports(ctx context.Context, createdBefore time.Time) (int64, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM data_exports WHERE created_at < ?`, createdBefore)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "cleaning up data exports")
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExports(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Lifecycle", testDataExportsLifecycle},
+		{"Cleanup", testDataExportsCleanup},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testDataExportsLifecycle(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	_, err := ds.DataExport(ctx, 999)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.CompleteDataExport(ctx, 999, nil, "")))
+
+	opts := json.RawMessage(`{"list_options":{}}`)
+	e1, err := ds.NewDataExport(ctx, &fleet.DataExport{
+		UserID:   user.ID,
+		Resource: fleet.DataExportResourceHosts,
+		Format:   fleet.DataExportFormatCSV,
+		Filename: "Hosts 2024-05-07.csv",
+		Options:  opts,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, e1.ID)
+	require.Equal(t, fleet.DataExportStatusPending, e1.Status)
+	require.JSONEq(t, string(opts), string(e1.Options))
+	require.Nil(t, e1.CompletedAt)
+
+	e2, err := ds.NewDataExport(ctx, &fleet.DataExport{
+		UserID:   user.ID,
+		Resource: fleet.DataExportResourceSoftware,
+		Format:   fleet.DataExportFormatXLSX,
+		Filename: "Software 2024-05-07.xlsx",
+		Options:  json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	pending, err := ds.ListPendingDataExports(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, e1.ID, pending[0].ID)
+	require.Equal(t, e2.ID, pending[1].ID)
+
+	data, err := ds.DataExportContent(ctx, e1.ID)
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	require.NoError(t, ds.CompleteDataExport(ctx, e1.ID, []byte("a,b\n"), ""))
+	require.NoError(t, ds.CompleteDataExport(ctx, e2.ID, []byte("ignored"), "boom"))
+
+	pending, err = ds.ListPendingDataExports(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	got, err := ds.DataExport(ctx, e1.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.DataExportStatusCompleted, got.Status)
+	require.EqualValues(t, 4, got.Size)
+	require.Nil(t, got.ErrorMessage)
+	require.NotNil(t, got.CompletedAt)
+	require.Nil(t, got.Data)
+
+	data, err = ds.DataExportContent(ctx, e1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n", string(data))
+
+	got, err = ds.DataExport(ctx, e2.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.DataExportStatusFailed, got.Status)
+	require.Zero(t, got.Size)
+	require.Equal(t, ptr.String("boom"), got.ErrorMessage)
+
+	data, err = ds.DataExportContent(ctx, e2.ID)
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	// deleting the user deletes its exports
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+	_, err = ds.DataExport(ctx, e1.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testDataExportsCleanup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	newExport := func() *fleet.DataExport {
+		e, err := ds.NewDataExport(ctx, &fleet.DataExport{
+			UserID:   user.ID,
+			Resource: fleet.DataExportResourceVulnerabilities,
+			Format:   fleet.DataExportFormatCSV,
+			Filename: "Vulnerabilities.csv",
+			Options:  json.RawMessage(`{}`),
+		})
+		require.NoError(t, err)
+		return e
+	}
+	old, recent := newExport(), newExport()
+
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE data_exports SET created_at = ? WHERE id = ?`, time.Now().Add(-48*time.Hour), old.ID)
+		return err
+	})
+
+	n, err := ds.CleanupDataExports(ctx, time.Now().Add(-fleet.DataExportRetention))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	_, err = ds.DataExport(ctx, old.ID)
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.DataExport(ctx, recent.ID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,192 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// tableSizeSnapshotsRetention is the retention period of the table size
+	// snapshots.
+	tableSizeSnapshotsRetention = 30 * 24 * time.Hour
+	// tableGrowthTolerance is the tolerance on the age of the snapshot used to
+	// compute the growth of a table over a period, as the snapshots are not
+	// taken exactly a day apart.
+	tableGrowthTolerance = time.Hour
+)
+
+var tableNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+func (ds *Datastore) ListDatabaseMaintenanceRuns(ctx context.Context) ([]*fleet.DatabaseMaintenanceRun, error) {
+	var runs []*fleet.DatabaseMaintenanceRun
+	// using the writer as the last runs decide which tasks are due.
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &runs, `
+		SELECT name, last_run_at, duration_ms, error
+		FROM database_maintenance_runs
+		ORDER BY name`,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing database maintenance runs")
+	}
+	return runs, nil
+}
+
+func (ds *Datastore) RecordDatabaseMaintenanceRun(ctx context.Context, run *fleet.DatabaseMaintenanceRun) error {
+	const stmt = `
+		INSERT INTO database_maintenance_runs (name, last_run_at, duration_ms, error)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_run_at = VALUES(last_run_at),
+			duration_ms = VALUES(duration_ms),
+			error = VALUES(error)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, run.Name, run.LastRunAt, run.DurationMS, run.Error); err != nil {
+		return ctxerr.Wrap(ctx, err, "recording database maintenance run")
+	}
+	return nil
+}
+
+func (ds *Datastore) SnapshotTableSizes(ctx context.Context) error {
+	// the sizes are the estimates of MySQL, which may be cached depending on
+	// information_schema_stats_expiry.
+	const insertStmt = `
+		INSERT INTO table_size_snapshots (table_name, table_rows, data_bytes, index_bytes, free_bytes, created_at)
+		SELECT
+			TABLE_NAME,
+			COALESCE(TABLE_ROWS, 0),
+			COALESCE(DATA_LENGTH, 0),
+			COALESCE(INDEX_LENGTH, 0),
+			COALESCE(DATA_FREE, 0),
+			?
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'`
+	const deleteStmt = `DELETE FROM table_size_snapshots WHERE created_at < ?`
+
+	now := ds.clock.Now().UTC().Truncate(time.Second)
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, insertStmt, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "inserting table size snapshots")
+		}
+		if _, err := tx.ExecContext(ctx, deleteStmt, now.Add(-tableSizeSnapshotsRetention)); err != nil {
+			return ctxerr.Wrap(ctx, err, "deleting expired table size snapshots")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListTableGrowth(ctx context.Context) ([]*fleet.TableGrowth, error) {
+	var latest sql.NullTime
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &latest, `SELECT MAX(created_at) FROM table_size_snapshots`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "getting latest table size snapshot")
+	}
+	if !latest.Valid {
+		return []*fleet.TableGrowth{}, nil
+	}
+
+	var snapshots []*fleet.TableGrowth
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &snapshots, `
+		SELECT table_name, table_rows, data_bytes, index_bytes, free_bytes, created_at
+		FROM table_size_snapshots
+		WHERE created_at >= ?
+		ORDER BY table_name, created_at`,
+		latest.Time.Add(-7*24*time.Hour-tableGrowthTolerance),
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing table size snapshots")
+	}
+	return computeTableGrowth(snapshots, latest.Time), nil
+}
+
+// computeTableGrowth returns the growth of the tables of the snapshots taken
+// at latest, given all their snapshots of the last week sorted by table name
+// and time. The largest tables are first.
+func computeTableGrowth(snapshots []*fleet.TableGrowth, latest time.Time) []*fleet.TableGrowth {
+	byTable := make(map[string][]*fleet.TableGrowth)
+	for _, s := range snapshots {
+		byTable[s.TableName] = append(byTable[s.TableName], s)
+	}
+
+	// baseline returns the most recent snapshot taken at least period before
+	// the latest one.
+	baseline := func(snaps []*fleet.TableGrowth, period time.Duration) *fleet.TableGrowth {
+		var base *fleet.TableGrowth
+		for _, s := range snaps {
+			if s.MeasuredAt.After(latest.Add(-period + tableGrowthTolerance)) {
+				break
+			}
+			base = s
+		}
+		return base
+	}
+
+	growth := make([]*fleet.TableGrowth, 0, len(byTable))
+	for _, snaps := range byTable {
+		cur := snaps[len(snaps)-1]
+		if !cur.MeasuredAt.Equal(latest) {
+			// the table no longer exists
+			continue
+		}
+		if base := baseline(snaps, 24*time.Hour); base != nil {
+			cur.RowsGrowth1d = ptr.Int64(cur.Rows - base.Rows)
+			cur.BytesGrowth1d = ptr.Int64(cur.TotalBytes() - base.TotalBytes())
+		}
+		if base := baseline(snaps, 7*24*time.Hour); base != nil {
+			cur.RowsGrowth7d = ptr.Int64(cur.Rows - base.Rows)
+			cur.BytesGrowth7d = ptr.Int64(cur.TotalBytes() - base.TotalBytes())
+		}
+		growth = append(growth, cur)
+	}
+	sort.Slice(growth, func(i, j int) bool {
+		if growth[i].TotalBytes() != growth[j].TotalBytes() {
+			return growth[i].TotalBytes() > growth[j].TotalBytes()
+		}
+		return growth[i].TableName < growth[j].TableName
+	})
+	return growth
+}
+
+func (ds *Datastore) AnalyzeTables(ctx context.Context, tables []string) error {
+	return ds.maintainTables(ctx, "ANALYZE", tables)
+}
+
+func (ds *Datastore) OptimizeTables(ctx context.Context, tables []string) error {
+	return ds.maintainTables(ctx, "OPTIMIZE", tables)
+}
+
+// maintainTables runs the table maintenance statement (ANALYZE or OPTIMIZE)
+// on each table, and returns the errors reported by MySQL.
+func (ds *Datastore) maintainTables(ctx context.Context, op string, tables []string) error {
+	var errs []error
+	for _, table := range tables {
+		if !tableNameRegexp.MatchString(table) {
+			return ctxerr.Errorf(ctx, "invalid table name: %q", table)
+		}
+
+		var results []struct {
+			Table   string `db:"Table"`
+			Op      string `db:"Op"`
+			MsgType string `db:"Msg_type"`
+			MsgText string `db:"Msg_text"`
+		}
+		if err := sqlx.SelectContext(ctx, ds.writer(ctx), &results, fmt.Sprintf("%s TABLE `%s`", op, table)); err != nil {
+			return ctxerr.Wrapf(ctx, err, "%s table %s", strings.ToLower(op), table)
+		}
+		for _, res := range results {
+			if strings.EqualFold(res.MsgType, "error") {
+				errs = append(errs, fmt.Errorf("%s table %s: %s", strings.ToLower(op), table, res.MsgText))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+	return nil
+}
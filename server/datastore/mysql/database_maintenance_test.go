@@ -0,0 +1,165 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseMaintenance(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Runs", testDatabaseMaintenanceRuns},
+		{"TableGrowth", testDatabaseMaintenanceTableGrowth},
+		{"AnalyzeOptimizeTables", testDatabaseMaintenanceAnalyzeOptimizeTables},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testDatabaseMaintenanceRuns(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	runs, err := ds.ListDatabaseMaintenanceRuns(ctx)
+	require.NoError(t, err)
+	require.Empty(t, runs)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, ds.RecordDatabaseMaintenanceRun(ctx, &fleet.DatabaseMaintenanceRun{Name: "b", LastRunAt: now, DurationMS: 10}))
+	require.NoError(t, ds.RecordDatabaseMaintenanceRun(ctx, &fleet.DatabaseMaintenanceRun{Name: "a", LastRunAt: now, Error: ptr.String("failed")}))
+
+	runs, err = ds.ListDatabaseMaintenanceRuns(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, "a", runs[0].Name)
+	require.Equal(t, "failed", *runs[0].Error)
+	require.Equal(t, "b", runs[1].Name)
+	require.EqualValues(t, 10, runs[1].DurationMS)
+	require.Nil(t, runs[1].Error)
+	require.True(t, now.Equal(runs[1].LastRunAt))
+
+	// the next run replaces the previous one
+	later := now.Add(time.Hour)
+	require.NoError(t, ds.RecordDatabaseMaintenanceRun(ctx, &fleet.DatabaseMaintenanceRun{Name: "a", LastRunAt: later, DurationMS: 5}))
+	runs, err = ds.ListDatabaseMaintenanceRuns(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.True(t, later.Equal(runs[0].LastRunAt))
+	require.EqualValues(t, 5, runs[0].DurationMS)
+	require.Nil(t, runs[0].Error)
+}
+
+func testDatabaseMaintenanceTableGrowth(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	mc := ds.clock.(*clock.MockClock)
+
+	growth, err := ds.ListTableGrowth(ctx)
+	require.NoError(t, err)
+	require.Empty(t, growth)
+
+	findTable := func(growth []*fleet.TableGrowth, name string) *fleet.TableGrowth {
+		for _, g := range growth {
+			if g.TableName == name {
+				return g
+			}
+		}
+		require.FailNow(t, "table not found", name)
+		return nil
+	}
+
+	require.NoError(t, ds.SnapshotTableSizes(ctx))
+	growth, err = ds.ListTableGrowth(ctx)
+	require.NoError(t, err)
+	hosts := findTable(growth, "hosts")
+	require.Nil(t, hosts.RowsGrowth1d)
+	require.Nil(t, hosts.BytesGrowth7d)
+	for i := 1; i < len(growth); i++ {
+		require.GreaterOrEqual(t, growth[i-1].TotalBytes(), growth[i].TotalBytes())
+	}
+
+	mc.AddTime(24 * time.Hour)
+	require.NoError(t, ds.SnapshotTableSizes(ctx))
+	growth, err = ds.ListTableGrowth(ctx)
+	require.NoError(t, err)
+	hosts = findTable(growth, "hosts")
+	require.NotNil(t, hosts.RowsGrowth1d)
+	require.NotNil(t, hosts.BytesGrowth1d)
+	require.Nil(t, hosts.RowsGrowth7d)
+
+	mc.AddTime(6 * 24 * time.Hour)
+	require.NoError(t, ds.SnapshotTableSizes(ctx))
+	growth, err = ds.ListTableGrowth(ctx)
+	require.NoError(t, err)
+	hosts = findTable(growth, "hosts")
+	require.NotNil(t, hosts.RowsGrowth1d)
+	require.NotNil(t, hosts.RowsGrowth7d)
+
+	// the snapshots older than the retention period are deleted
+	mc.AddTime(tableSizeSnapshotsRetention + time.Hour)
+	require.NoError(t, ds.SnapshotTableSizes(ctx))
+	var snapshotTimes int
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &snapshotTimes, `SELECT COUNT(DISTINCT created_at) FROM table_size_snapshots`))
+	require.Equal(t, 1, snapshotTimes)
+}
+
+func testDatabaseMaintenanceAnalyzeOptimizeTables(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	require.NoError(t, ds.AnalyzeTables(ctx, []string{"hosts", "labels"}))
+	require.NoError(t, ds.OptimizeTables(ctx, []string{"table_size_snapshots"}))
+
+	// MySQL reports the errors in the results of the statement
+	err := ds.AnalyzeTables(ctx, []string{"no_such_table"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "analyze table no_such_table")
+
+	err = ds.AnalyzeTables(ctx, []string{"hosts; DROP TABLE hosts"})
+	require.ErrorContains(t, err, "invalid table name")
+}
+
+func TestComputeTableGrowth(t *testing.T) {
+	latest := time.Date(2024, 6, 10, 3, 0, 0, 0, time.UTC)
+	snapshot := func(table string, age time.Duration, rows, bytes int64) *fleet.TableGrowth {
+		return &fleet.TableGrowth{TableName: table, Rows: rows, DataBytes: bytes, MeasuredAt: latest.Add(-age)}
+	}
+	snapshots := []*fleet.TableGrowth{
+		snapshot("a", 7*24*time.Hour+30*time.Minute, 10, 100),
+		snapshot("a", 2*24*time.Hour, 50, 500),
+		snapshot("a", 24*time.Hour-10*time.Minute, 90, 900),
+		snapshot("a", 0, 100, 1000),
+		// dropped table
+		snapshot("b", 24*time.Hour, 10, 10),
+		snapshot("c", 23*time.Hour, 1, 5000),
+		snapshot("c", 0, 2, 6000),
+	}
+
+	growth := computeTableGrowth(snapshots, latest)
+	require.Len(t, growth, 2)
+
+	// largest table first
+	require.Equal(t, "c", growth[0].TableName)
+	require.EqualValues(t, 1, *growth[0].RowsGrowth1d)
+	require.EqualValues(t, 1000, *growth[0].BytesGrowth1d)
+	require.Nil(t, growth[0].RowsGrowth7d)
+
+	require.Equal(t, "a", growth[1].TableName)
+	require.EqualValues(t, 100, growth[1].Rows)
+	require.EqualValues(t, 10, *growth[1].RowsGrowth1d)
+	require.EqualValues(t, 100, *growth[1].BytesGrowth1d)
+	require.EqualValues(t, 90, *growth[1].RowsGrowth7d)
+	require.EqualValues(t, 900, *growth[1].BytesGrowth7d)
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ListDuplicateHosts(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHostSet, error) {
+	// the generic serial numbers are filtered out when grouping the hosts, the
+	// query only needs to narrow down the candidates.
+	stmt := fmt.Sprintf(`
+	SELECT
+		h.id,
+		h.hostname,
+		h.computer_name,
+		h.hardware_serial,
+		h.uuid,
+		h.platform,
+		h.team_id,
+		h.created_at,
+		COALESCE(hst.seen_time, h.created_at) AS seen_time
+	FROM hosts h
+	LEFT JOIN host_seen_times hst ON hst.host_id = h.id
+	WHERE
+		(
+			h.hardware_serial IN (
+				SELECT hardware_serial FROM hosts WHERE hardware_serial != '' GROUP BY hardware_serial HAVING COUNT(*) > 1
+			) OR
+			h.uuid IN (
+				SELECT uuid FROM hosts WHERE uuid != '' GROUP BY uuid HAVING COUNT(*) > 1
+			)
+		) AND %s
+	ORDER BY h.id`, ds.whereFilterHostsByTeams(filter, "h"))
+
+	var hosts []*fleet.DuplicateHost
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing duplicate hosts")
+	}
+	return fleet.GroupDuplicateHosts(hosts), nil
+}
+
+// mergedHostRefs are the tables referencing hosts by host_id that are moved to
+// the target host when duplicate hosts are merged. Rows that conflict with
+// the target host's own rows are left on the duplicate host and deleted with
+// it, so the target host's data takes precedence.
+var mergedHostRefs = []string{
+	"host_activities",
+	"host_script_results",
+	"host_log_collections",
+	"host_file_retrievals",
+	"host_pprof_captures",
+	"host_remote_queries",
+	"host_mdm",
+	"host_disk_encryption_keys",
+	"host_dep_assignments",
+	"host_software",
+	"host_group_members",
+	"host_cve_detections",
+}
+
+func (ds *Datastore) MergeHosts(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
+	if len(duplicateHostIDs) == 0 {
+		return nil
+	}
+
+	type hostUUID struct {
+		ID   uint   `db:"id"`
+		UUID string `db:"uuid"`
+	}
+
+	var targetUUID string
+	if err := sqlx.GetContext(ctx, ds.writer(ctx), &targetUUID, `SELECT uuid FROM hosts WHERE id = ?`, targetHostID); err != nil {
+		if err == sql.ErrNoRows {
+			return ctxerr.Wrap(ctx, notFound("Host").WithID(targetHostID))
+		}
+		return ctxerr.Wrapf(ctx, err, "get uuid for host %d", targetHostID)
+	}
+
+	stmt, args, err := sqlx.In(`SELECT id, uuid FROM hosts WHERE id IN (?)`, duplicateHostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build select duplicate hosts")
+	}
+	var duplicates []hostUUID
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &duplicates, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "select duplicate hosts")
+	}
+	if len(duplicates) != len(duplicateHostIDs) {
+		return ctxerr.Wrap(ctx, notFound("Host"), "some duplicate hosts do not exist")
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// the installed paths are moved only for the software that the target
+		// host does not have, before the host_software rows are moved.
+		stmt, args, err := sqlx.In(`
+			UPDATE host_software_installed_paths
+			SET host_id = ?
+			WHERE host_id IN (?) AND
+				software_id NOT IN (SELECT software_id FROM host_software WHERE host_id = ?)`,
+			targetHostID, duplicateHostIDs, targetHostID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build update host_software_installed_paths")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "merge host_software_installed_paths")
+		}
+
+		for _, table := range mergedHostRefs {
+			stmt, args, err := sqlx.In(fmt.Sprintf(`UPDATE IGNORE %s SET host_id = ? WHERE host_id IN (?)`, table), targetHostID, duplicateHostIDs)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "build update %s", table)
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrapf(ctx, err, "merge %s", table)
+			}
+		}
+
+		// host_dep_assignments is not cleared when a host is deleted, remove the
+		// duplicates' rows that were not moved.
+		stmt, args, err = sqlx.In(`DELETE FROM host_dep_assignments WHERE host_id IN (?)`, duplicateHostIDs)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build delete host_dep_assignments")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete duplicate host_dep_assignments")
+		}
+
+		for _, dup := range duplicates {
+			// the MDM state of a duplicate host that shares the target host's uuid
+			// is already the target host's, it must not be deleted.
+			dupUUID := dup.UUID
+			if dupUUID == targetUUID {
+				dupUUID = ""
+			}
+			if dupUUID != "" && targetUUID != "" {
+				for table, col := range additionalHostRefsByUUID {
+					if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE IGNORE `%s` SET `%s` = ? WHERE `%s` = ?", table, col, col), targetUUID, dupUUID); err != nil {
+						return ctxerr.Wrapf(ctx, err, "merge %s for host uuid %s", table, dupUUID)
+					}
+				}
+			}
+
+			if err := deleteHostDB(ctx, tx, dup.ID, dupUUID); err != nil {
+				return ctxerr.Wrapf(ctx, err, "delete merged host %d", dup.ID)
+			}
+		}
+		return nil
+	})
+}
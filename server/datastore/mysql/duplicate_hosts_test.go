@@ -0,0 +1,167 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateHosts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"List", testListDuplicateHosts},
+		{"Merge", testMergeHosts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func newDuplicateTestHost(t *testing.T, ds *Datastore, serial, hostUUID string, teamID *uint, seenTime time.Time) *fleet.Host {
+	h, err := ds.NewHost(context.Background(), &fleet.Host{
+		Hostname:        "host-" + uuid.NewString(),
+		OsqueryHostID:   ptr.String(uuid.NewString()),
+		NodeKey:         ptr.String(uuid.NewString()),
+		UUID:            hostUUID,
+		HardwareSerial:  serial,
+		Platform:        "darwin",
+		TeamID:          teamID,
+		DetailUpdatedAt: seenTime,
+		LabelUpdatedAt:  seenTime,
+		PolicyUpdatedAt: seenTime,
+		SeenTime:        seenTime,
+	})
+	require.NoError(t, err)
+	return h
+}
+
+func testListDuplicateHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	sets, err := ds.ListDuplicateHosts(ctx, fleet.TeamFilter{User: test.UserAdmin})
+	require.NoError(t, err)
+	require.Empty(t, sets)
+
+	h1 := newDuplicateTestHost(t, ds, "S1", "U1", nil, now.Add(-time.Hour))
+	h2 := newDuplicateTestHost(t, ds, "S1", "U2", nil, now)
+	h3 := newDuplicateTestHost(t, ds, "S3", "U2", nil, now.Add(-2*time.Hour))
+	// generic serial numbers are not duplicates
+	newDuplicateTestHost(t, ds, "0", "U4", nil, now)
+	newDuplicateTestHost(t, ds, "0", "U5", nil, now)
+	// unique host
+	newDuplicateTestHost(t, ds, "S6", "U6", nil, now)
+	h7 := newDuplicateTestHost(t, ds, "S7", "U7", &team.ID, now.Add(-time.Hour))
+	h8 := newDuplicateTestHost(t, ds, "S7", "U8", &team.ID, now)
+
+	hostIDs := func(sets []*fleet.DuplicateHostSet) [][]uint {
+		var ids [][]uint
+		for _, set := range sets {
+			var setIDs []uint
+			for _, h := range set.Hosts {
+				setIDs = append(setIDs, h.ID)
+			}
+			ids = append(ids, setIDs)
+		}
+		return ids
+	}
+
+	sets, err = ds.ListDuplicateHosts(ctx, fleet.TeamFilter{User: test.UserAdmin})
+	require.NoError(t, err)
+	require.Equal(t, [][]uint{{h2.ID, h1.ID, h3.ID}, {h8.ID, h7.ID}}, hostIDs(sets))
+	require.Equal(t, now, sets[0].Hosts[0].SeenTime.UTC())
+
+	teamUser := &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}
+	sets, err = ds.ListDuplicateHosts(ctx, fleet.TeamFilter{User: teamUser, IncludeObserver: true})
+	require.NoError(t, err)
+	require.Equal(t, [][]uint{{h8.ID, h7.ID}}, hostIDs(sets))
+}
+
+func testMergeHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now()
+
+	target := newDuplicateTestHost(t, ds, "S1", "U1", nil, now)
+	dup1 := newDuplicateTestHost(t, ds, "S1", "U2", nil, now.Add(-time.Hour))
+	dup2 := newDuplicateTestHost(t, ds, "S3", "U1", nil, now.Add(-2*time.Hour))
+
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		// the target host and dup1 both have software 1, only dup1 has software 2
+		if _, err := q.ExecContext(ctx, `INSERT INTO host_software (host_id, software_id) VALUES (?, 1), (?, 1), (?, 2)`,
+			target.ID, dup1.ID, dup1.ID); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `INSERT INTO host_software_installed_paths (host_id, software_id, installed_path) VALUES (?, 1, '/a'), (?, 1, '/b'), (?, 2, '/c')`,
+			target.ID, dup1.ID, dup1.ID); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `INSERT INTO host_mdm (host_id, enrolled, server_url) VALUES (?, 1, 'https://dup1')`, dup1.ID); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `INSERT INTO host_script_results (host_id, execution_id, output) VALUES (?, 'exec1', '')`, dup2.ID); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO host_mdm_apple_profiles (profile_uuid, profile_identifier, host_uuid, command_uuid, checksum)
+			VALUES ('p1', 'p1', 'U1', 'c1', 'checksum'), ('p1', 'p1', 'U2', 'c2', 'checksum'), ('p2', 'p2', 'U2', 'c3', 'checksum')`)
+		return err
+	})
+
+	err := ds.MergeHosts(ctx, target.ID, []uint{dup1.ID, 999})
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+
+	err = ds.MergeHosts(ctx, target.ID, []uint{dup1.ID, dup2.ID})
+	require.NoError(t, err)
+
+	for _, id := range []uint{dup1.ID, dup2.ID} {
+		_, err = ds.Host(ctx, id)
+		require.ErrorAs(t, err, &nfe)
+	}
+
+	var softwareIDs []uint
+	var paths []string
+	var mdmServerURL string
+	var scriptHostID uint
+	var profiles []string
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		if err := sqlx.SelectContext(ctx, q, &softwareIDs, `SELECT software_id FROM host_software WHERE host_id = ? ORDER BY software_id`, target.ID); err != nil {
+			return err
+		}
+		if err := sqlx.SelectContext(ctx, q, &paths, `SELECT installed_path FROM host_software_installed_paths ORDER BY installed_path`); err != nil {
+			return err
+		}
+		if err := sqlx.GetContext(ctx, q, &mdmServerURL, `SELECT server_url FROM host_mdm WHERE host_id = ?`, target.ID); err != nil {
+			return err
+		}
+		if err := sqlx.GetContext(ctx, q, &scriptHostID, `SELECT host_id FROM host_script_results WHERE execution_id = 'exec1'`); err != nil {
+			return err
+		}
+		return sqlx.SelectContext(ctx, q, &profiles, `SELECT CONCAT(host_uuid, ':', command_uuid) FROM host_mdm_apple_profiles ORDER BY command_uuid`)
+	})
+	require.Equal(t, []uint{1, 2}, softwareIDs)
+	require.Equal(t, []string{"/a", "/c"}, paths)
+	require.Equal(t, "https://dup1", mdmServerURL)
+	require.Equal(t, target.ID, scriptHostID)
+	// the target host's profile takes precedence, dup2 shares the target
+	// host's uuid so its profiles are kept.
+	require.Equal(t, []string{"U1:c1", "U1:c3"}, profiles)
+}
@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const emailTemplateSelectColumns = `
+	name,
+	subject,
+	body,
+	sender_name,
+	sender_address,
+	updated_at
+`
+
+func (ds *Datastore) ListEmailTemplates(ctx context.Context) ([]*fleet.EmailTemplate, error) {
+	stmt := `SELECT ` + emailTemplateSelectColumns + ` FROM email_templates ORDER BY name`
+
+	var tmpls []*fleet.EmailTemplate
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &tmpls, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list email templates")
+	}
+	for _, tmpl := range tmpls {
+		tmpl.Custom = true
+	}
+	return tmpls, nil
+}
+
+func (ds *Datastore) EmailTemplate(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+	return ds.emailTemplate(ctx, ds.reader(ctx), name)
+}
+
+func (ds *Datastore) emailTemplate(ctx context.Context, q sqlx.QueryerContext, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+	stmt := `SELECT ` + emailTemplateSelectColumns + ` FROM email_templates WHERE name = ?`
+
+	var tmpl fleet.EmailTemplate
+	if err := sqlx.GetContext(ctx, q, &tmpl, stmt, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("EmailTemplate").WithName(string(name)))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get email template")
+	}
+	tmpl.Custom = true
+	return &tmpl, nil
+}
+
+func (ds *Datastore) SaveEmailTemplate(ctx context.Context, tmpl *fleet.EmailTemplate) (*fleet.EmailTemplate, error) {
+	const stmt = `
+		INSERT INTO email_templates (name, subject, body, sender_name, sender_address)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			subject = VALUES(subject),
+			body = VALUES(body),
+			sender_name = VALUES(sender_name),
+			sender_address = VALUES(sender_address)
+	`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, tmpl.Name, tmpl.Subject, tmpl.Body, tmpl.SenderName, tmpl.SenderAddress); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save email template")
+	}
+	return ds.emailTemplate(ctx, ds.writer(ctx), tmpl.Name)
+}
+
+func (ds *Datastore) DeleteEmailTemplate(ctx context.Context, name fleet.EmailTemplateName) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM email_templates WHERE name = ?`, name); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete email template")
+	}
+	return nil
+}
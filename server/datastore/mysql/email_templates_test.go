@@ -0,0 +1,89 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailTemplates(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testEmailTemplatesCRUD},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testEmailTemplatesCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tmpls, err := ds.ListEmailTemplates(ctx)
+	require.NoError(t, err)
+	require.Empty(t, tmpls)
+
+	_, err = ds.EmailTemplate(ctx, fleet.EmailTemplateInvite)
+	require.True(t, fleet.IsNotFound(err))
+
+	invite, err := ds.SaveEmailTemplate(ctx, &fleet.EmailTemplate{
+		Name:          fleet.EmailTemplateInvite,
+		Subject:       "Join Acme",
+		Body:          "{{.Token}}",
+		SenderAddress: "it@acme.com",
+	})
+	require.NoError(t, err)
+	require.True(t, invite.Custom)
+	require.Equal(t, "Join Acme", invite.Subject)
+	require.Equal(t, "{{.Token}}", invite.Body)
+	require.Empty(t, invite.SenderName)
+	require.Equal(t, "it@acme.com", invite.SenderAddress)
+	require.False(t, invite.UpdatedAt.IsZero())
+
+	_, err = ds.SaveEmailTemplate(ctx, &fleet.EmailTemplate{
+		Name:       fleet.EmailTemplatePasswordReset,
+		SenderName: "Acme IT",
+	})
+	require.NoError(t, err)
+
+	// replace the invite customization
+	invite, err = ds.SaveEmailTemplate(ctx, &fleet.EmailTemplate{
+		Name:       fleet.EmailTemplateInvite,
+		Body:       "<p>{{.Token}}</p>",
+		SenderName: "Acme IT",
+	})
+	require.NoError(t, err)
+	require.Empty(t, invite.Subject)
+	require.Equal(t, "<p>{{.Token}}</p>", invite.Body)
+	require.Equal(t, "Acme IT", invite.SenderName)
+	require.Empty(t, invite.SenderAddress)
+
+	got, err := ds.EmailTemplate(ctx, fleet.EmailTemplateInvite)
+	require.NoError(t, err)
+	require.Equal(t, invite, got)
+
+	tmpls, err = ds.ListEmailTemplates(ctx)
+	require.NoError(t, err)
+	require.Len(t, tmpls, 2)
+	require.Equal(t, fleet.EmailTemplateInvite, tmpls[0].Name)
+	require.Equal(t, fleet.EmailTemplatePasswordReset, tmpls[1].Name)
+
+	require.NoError(t, ds.DeleteEmailTemplate(ctx, fleet.EmailTemplateInvite))
+	_, err = ds.EmailTemplate(ctx, fleet.EmailTemplateInvite)
+	require.True(t, fleet.IsNotFound(err))
+	// deleting a template that is not customized does not fail
+	require.NoError(t, ds.DeleteEmailTemplate(ctx, fleet.EmailTemplateMDMMigration))
+
+	tmpls, err = ds.ListEmailTemplates(ctx)
+	require.NoError(t, err)
+	require.Len(t, tmpls, 1)
+}
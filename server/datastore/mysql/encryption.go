@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// encrypt encrypts the plaintext with AES-256-GCM using the first 32 bytes of
+// the server private key. The random nonce is prepended to the returned
+// ciphertext.
+func encrypt(plainText []byte, privateKey string) ([]byte, error) {
+	aesGCM, err := newServerKeyCipher(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return sealAESGCM(aesGCM, plainText)
+}
+
+// decrypt decrypts a ciphertext produced by encrypt.
+func decrypt(encrypted []byte, privateKey string) ([]byte, error) {
+	aesGCM, err := newServerKeyCipher(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return openAESGCM(aesGCM, encrypted)
+}
+
+func sealAESGCM(aesGCM cipher.AEAD, plainText []byte) ([]byte, error) {
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aesGCM.Seal(nonce, nonce, plainText, nil), nil
+}
+
+func openAESGCM(aesGCM cipher.AEAD, encrypted []byte) ([]byte, error) {
+	nonceSize := aesGCM.NonceSize()
+	if len(encrypted) < nonceSize {
+		return nil, errors.New("malformed ciphertext")
+	}
+	plainText, err := aesGCM.Open(nil, encrypted[:nonceSize], encrypted[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plainText, nil
+}
+
+func newServerKeyCipher(privateKey string) (cipher.AEAD, error) {
+	if len(privateKey) < 32 {
+		return nil, errors.New("server private key is missing or shorter than 32 bytes")
+	}
+	return newAESGCM([]byte(privateKey[:32]))
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return aesGCM, nil
+}
@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostCertificates(ctx context.Context, hostID uint, origin fleet.HostCertificateOrigin, certs []*fleet.HostCertificate) error {
+	for _, c := range certs {
+		if hostID != c.HostID {
+			return ctxerr.Errorf(ctx, "host certificates are not all for the provided host id %d, found %d", hostID, c.HostID)
+		}
+	}
+
+	const (
+		// only the certificates reported by the same origin are deleted, a
+		// certificate reported by both osquery and MDM belongs to the last one
+		// that reported it.
+		delStmt = `DELETE FROM host_certificates WHERE host_id = ? AND origin = ?`
+
+		insStmt = `
+      INSERT INTO host_certificates
+        (host_id, sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after, source, origin, is_identity)
+      VALUES %s
+      ON DUPLICATE KEY UPDATE
+        common_name = VALUES(common_name),
+        subject = VALUES(subject),
+        issuer = VALUES(issuer),
+        ca = VALUES(ca),
+        self_signed = VALUES(self_signed),
+        not_valid_before = VALUES(not_valid_before),
+        not_valid_after = VALUES(not_valid_after),
+        source = VALUES(source),
+        origin = VALUES(origin),
+        is_identity = IF(VALUES(origin) = 'mdm', VALUES(is_identity), is_identity)`
+		insPart = `(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?),`
+
+		batchSize = 500
+	)
+
+	// ignore multiple rows with the same fingerprint, the same certificate may
+	// be found in multiple keychains/stores.
+	uniq := make([]*fleet.HostCertificate, 0, len(certs))
+	sha1s := make([]string, 0, len(certs))
+	seen := make(map[string]struct{}, len(certs))
+	for _, c := range certs {
+		if _, ok := seen[c.SHA1]; ok {
+			continue
+		}
+		seen[c.SHA1] = struct{}{}
+		uniq = append(uniq, c)
+		sha1s = append(sha1s, c.SHA1)
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		stmt, args := delStmt, []any{hostID, origin}
+		if len(sha1s) > 0 {
+			var err error
+			stmt, args, err = sqlx.In(delStmt+` AND sha1 NOT IN (?)`, hostID, origin, sha1s)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "prepare delete statement")
+			}
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete host certificates")
+		}
+
+		for i := 0; i < len(uniq); i += batchSize {
+			end := i + batchSize
+			if end > len(uniq) {
+				end = len(uniq)
+			}
+			batch := uniq[i:end]
+
+			args := make([]any, 0, len(batch)*12)
+			for _, c := range batch {
+				args = append(args, hostID, c.SHA1, c.CommonName, c.Subject, c.Issuer, c.CA, c.SelfSigned,
+					c.NotValidBefore, c.NotValidAfter, c.Source, origin, c.IsIdentity)
+			}
+			values := strings.TrimSuffix(strings.Repeat(insPart, len(batch)), ",")
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(insStmt, values), args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host certificates")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostCertificates(ctx context.Context, hostID uint) ([]*fleet.HostCertificate, error) {
+	const stmt = `
+		SELECT
+			id, host_id, sha1, common_name, subject, issuer, ca, self_signed,
+			not_valid_before, not_valid_after, source, origin, is_identity
+		FROM host_certificates
+		WHERE host_id = ?
+		ORDER BY not_valid_after, sha1`
+
+	certs := []*fleet.HostCertificate{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &certs, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host certificates")
+	}
+	return certs, nil
+}
+
+// the attributes of a certificate are the same on all hosts for a given
+// fingerprint, MAX is used to select them in the aggregated queries.
+const selectCertificateInventoryColumns = `
+	hc.sha1,
+	MAX(hc.common_name) AS common_name,
+	MAX(hc.subject) AS subject,
+	MAX(hc.issuer) AS issuer,
+	MAX(hc.ca) AS ca,
+	MAX(hc.self_signed) AS self_signed,
+	MAX(hc.not_valid_before) AS not_valid_before,
+	MAX(hc.not_valid_after) AS not_valid_after,
+	COUNT(*) AS hosts_count`
+
+func (ds *Datastore) ListCertificateInventory(ctx context.Context, filter fleet.TeamFilter, opts fleet.CertificateInventoryListOptions) ([]*fleet.CertificateInventoryItem, int, *fleet.PaginationMetadata, error) {
+	var noTeam bool
+	if filter.TeamID != nil && *filter.TeamID == 0 {
+		// hosts in no team, the user filter is applied without team so that
+		// only the users that can see all hosts see those.
+		noTeam = true
+		filter.TeamID = nil
+	}
+
+	whereClause := ds.whereFilterHostsByTeams(filter, "h")
+	var args []any
+	if noTeam {
+		whereClause += ` AND h.team_id IS NULL`
+	}
+	if opts.ExpiringWithinDays != nil {
+		whereClause += ` AND hc.not_valid_after <= ?`
+		args = append(args, ds.clock.Now().UTC().Add(time.Duration(*opts.ExpiringWithinDays)*24*time.Hour))
+	}
+	if opts.CA != nil {
+		whereClause += ` AND hc.ca = ?`
+		args = append(args, *opts.CA)
+	}
+	whereClause, args = searchLike(whereClause, args, opts.ListOptions.MatchQuery, "hc.common_name", "hc.subject", "hc.issuer")
+
+	var count int
+	countStmt := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT hc.sha1)
+		FROM host_certificates hc
+		JOIN hosts h ON h.id = hc.host_id
+		WHERE %s`, whereClause)
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &count, countStmt, args...); err != nil {
+		return nil, 0, nil, ctxerr.Wrap(ctx, err, "count certificate inventory")
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT * FROM (
+			SELECT %s
+			FROM host_certificates hc
+			JOIN hosts h ON h.id = hc.host_id
+			WHERE %s
+			GROUP BY hc.sha1
+		) ci`, selectCertificateInventoryColumns, whereClause)
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, &opts.ListOptions)
+
+	items := []*fleet.CertificateInventoryItem{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &items, stmt, args...); err != nil {
+		return nil, 0, nil, ctxerr.Wrap(ctx, err, "list certificate inventory")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.ListOptions.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.ListOptions.Page > 0}
+		if len(items) > int(opts.ListOptions.PerPage) {
+			meta.HasNextResults = true
+			items = items[:len(items)-1]
+		}
+	}
+	return items, count, meta, nil
+}
+
+func (ds *Datastore) ListExpiringCertificates(ctx context.Context, from, to time.Time, hostsThreshold uint) ([]*fleet.CertificateInventoryItem, error) {
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM host_certificates hc
+		WHERE hc.not_valid_after >= ? AND hc.not_valid_after < ?
+		GROUP BY hc.sha1
+		HAVING hosts_count > ?
+		ORDER BY not_valid_after, hc.sha1`, selectCertificateInventoryColumns)
+
+	items := []*fleet.CertificateInventoryItem{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &items, stmt, from.UTC(), to.UTC(), hostsThreshold); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list expiring certificates")
+	}
+	return items, nil
+}
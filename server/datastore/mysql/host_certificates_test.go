@@ -0,0 +1,324 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostCertificates(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Replace", testHostCertificatesReplace},
+		{"ReplaceByOrigin", testHostCertificatesReplaceByOrigin},
+		{"Inventory", testHostCertificatesInventory},
+		{"Expiring", testHostCertificatesExpiring},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+const (
+	testCertSHA1A = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	testCertSHA1B = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	testCertSHA1C = "cccccccccccccccccccccccccccccccccccccccc"
+)
+
+func newTestHostCertificate(hostID uint, sha1, cn string, ca bool, notValidAfter time.Time) *fleet.HostCertificate {
+	return &fleet.HostCertificate{
+		HostID:         hostID,
+		SHA1:           sha1,
+		CommonName:     cn,
+		Subject:        "CN=" + cn,
+		Issuer:         "CN=Issuer",
+		CA:             ca,
+		NotValidBefore: notValidAfter.AddDate(-1, 0, 0),
+		NotValidAfter:  notValidAfter,
+		Source:         "/Library/Keychains/System.keychain",
+	}
+}
+
+func testHostCertificatesReplace(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+
+	getSHA1s := func() []string {
+		var sha1s []string
+		err := sqlx.SelectContext(ctx, ds.writer(ctx), &sha1s, `SELECT sha1 FROM host_certificates WHERE host_id = ? ORDER BY sha1`, h1.ID)
+		require.NoError(t, err)
+		return sha1s
+	}
+
+	// certificates must all be for the provided host
+	err := ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{newTestHostCertificate(h1.ID+1, testCertSHA1A, "a", false, now)})
+	require.Error(t, err)
+
+	// duplicate fingerprints are ignored
+	err = ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, now),
+		newTestHostCertificate(h1.ID, testCertSHA1B, "b", true, now),
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, now),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{testCertSHA1A, testCertSHA1B}, getSHA1s())
+
+	// b is removed, c is added
+	err = ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, now),
+		newTestHostCertificate(h1.ID, testCertSHA1C, "c", false, now),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{testCertSHA1A, testCertSHA1C}, getSHA1s())
+
+	// no certificate anymore
+	err = ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, nil)
+	require.NoError(t, err)
+	require.Empty(t, getSHA1s())
+}
+
+func testHostCertificatesReplaceByOrigin(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+
+	type result struct {
+		sha1       string
+		origin     fleet.HostCertificateOrigin
+		isIdentity bool
+	}
+	listCerts := func() []result {
+		certs, err := ds.ListHostCertificates(ctx, h1.ID)
+		require.NoError(t, err)
+		res := make([]result, 0, len(certs))
+		for _, c := range certs {
+			res = append(res, result{c.SHA1, c.Origin, c.IsIdentity})
+		}
+		return res
+	}
+
+	// no certificate yet
+	require.Empty(t, listCerts())
+
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, now),
+		newTestHostCertificate(h1.ID, testCertSHA1B, "b", true, now.AddDate(0, 0, 1)),
+	}))
+
+	// MDM reports b (an identity) and c, it takes over b
+	identity := newTestHostCertificate(h1.ID, testCertSHA1B, "b", true, now.AddDate(0, 0, 1))
+	identity.IsIdentity = true
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginMDM, []*fleet.HostCertificate{
+		identity,
+		newTestHostCertificate(h1.ID, testCertSHA1C, "c", false, now.AddDate(0, 0, 2)),
+	}))
+	require.Equal(t, []result{
+		{testCertSHA1A, fleet.HostCertificateOriginOsquery, false},
+		{testCertSHA1B, fleet.HostCertificateOriginMDM, true},
+		{testCertSHA1C, fleet.HostCertificateOriginMDM, false},
+	}, listCerts())
+
+	// osquery reports a and b again, the MDM certificate c is kept and b is
+	// still known to be an identity
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, now),
+		newTestHostCertificate(h1.ID, testCertSHA1B, "b", true, now.AddDate(0, 0, 1)),
+	}))
+	require.Equal(t, []result{
+		{testCertSHA1A, fleet.HostCertificateOriginOsquery, false},
+		{testCertSHA1B, fleet.HostCertificateOriginOsquery, true},
+		{testCertSHA1C, fleet.HostCertificateOriginMDM, false},
+	}, listCerts())
+
+	// MDM does not report any certificate anymore
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginMDM, nil))
+	require.Equal(t, []result{
+		{testCertSHA1A, fleet.HostCertificateOriginOsquery, false},
+		{testCertSHA1B, fleet.HostCertificateOriginOsquery, true},
+	}, listCerts())
+}
+
+func testHostCertificatesInventory(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+	h3 := test.NewHost(t, ds, "h3.local", "", "3", "uuid-3", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{h3.ID}))
+
+	// a expires in 10 days on all hosts, b is a CA expiring in a year on h1
+	// and h3, c is expired on h2
+	soon, later, expired := now.AddDate(0, 0, 10), now.AddDate(1, 0, 0), now.AddDate(0, 0, -1)
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h1.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h1.ID, testCertSHA1A, "a", false, soon),
+		newTestHostCertificate(h1.ID, testCertSHA1B, "b", true, later),
+	}))
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h2.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h2.ID, testCertSHA1A, "a", false, soon),
+		newTestHostCertificate(h2.ID, testCertSHA1C, "c", false, expired),
+	}))
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, h3.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		newTestHostCertificate(h3.ID, testCertSHA1A, "a", false, soon),
+		newTestHostCertificate(h3.ID, testCertSHA1B, "b", true, later),
+	}))
+
+	admin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
+	teamObserver := &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}
+
+	type result struct {
+		sha1  string
+		hosts uint
+	}
+	cases := []struct {
+		desc      string
+		filter    fleet.TeamFilter
+		opts      fleet.CertificateInventoryListOptions
+		want      []result
+		wantCount int
+	}{
+		{
+			desc:   "all certificates",
+			filter: fleet.TeamFilter{User: admin},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "not_valid_after"}},
+			want:   []result{{testCertSHA1C, 1}, {testCertSHA1A, 3}, {testCertSHA1B, 2}},
+		},
+		{
+			desc:   "ordered by hosts count",
+			filter: fleet.TeamFilter{User: admin},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "hosts_count", OrderDirection: fleet.OrderDescending}},
+			want:   []result{{testCertSHA1A, 3}, {testCertSHA1B, 2}, {testCertSHA1C, 1}},
+		},
+		{
+			desc:   "expiring within 30 days",
+			filter: fleet.TeamFilter{User: admin},
+			opts:   fleet.CertificateInventoryListOptions{ExpiringWithinDays: ptr.Uint(30), ListOptions: fleet.ListOptions{OrderKey: "not_valid_after"}},
+			want:   []result{{testCertSHA1C, 1}, {testCertSHA1A, 3}},
+		},
+		{
+			desc:   "certificate authorities",
+			filter: fleet.TeamFilter{User: admin},
+			opts:   fleet.CertificateInventoryListOptions{CA: ptr.Bool(true)},
+			want:   []result{{testCertSHA1B, 2}},
+		},
+		{
+			desc:   "search",
+			filter: fleet.TeamFilter{User: admin},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{MatchQuery: "CN=c"}},
+			want:   []result{{testCertSHA1C, 1}},
+		},
+		{
+			desc:   "team",
+			filter: fleet.TeamFilter{User: admin, TeamID: &team.ID},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "not_valid_after"}},
+			want:   []result{{testCertSHA1A, 1}, {testCertSHA1B, 1}},
+		},
+		{
+			desc:   "no team",
+			filter: fleet.TeamFilter{User: admin, TeamID: ptr.Uint(0)},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "not_valid_after"}},
+			want:   []result{{testCertSHA1C, 1}, {testCertSHA1A, 2}, {testCertSHA1B, 1}},
+		},
+		{
+			desc:   "team observer",
+			filter: fleet.TeamFilter{User: teamObserver, IncludeObserver: true},
+			opts:   fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "not_valid_after"}},
+			want:   []result{{testCertSHA1A, 1}, {testCertSHA1B, 1}},
+		},
+		{
+			desc:   "team observer, no team",
+			filter: fleet.TeamFilter{User: teamObserver, IncludeObserver: true, TeamID: ptr.Uint(0)},
+			want:   []result{},
+		},
+		{
+			desc:      "paginated",
+			filter:    fleet.TeamFilter{User: admin},
+			opts:      fleet.CertificateInventoryListOptions{ListOptions: fleet.ListOptions{OrderKey: "not_valid_after", PerPage: 2, Page: 1}},
+			want:      []result{{testCertSHA1B, 2}},
+			wantCount: 3,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			items, count, _, err := ds.ListCertificateInventory(ctx, c.filter, c.opts)
+			require.NoError(t, err)
+
+			got := make([]result, 0, len(items))
+			for _, item := range items {
+				got = append(got, result{item.SHA1, item.HostsCount})
+			}
+			require.ElementsMatch(t, c.want, got)
+			if c.opts.ListOptions.OrderKey != "" {
+				require.Equal(t, c.want, got)
+			}
+			wantCount := c.wantCount
+			if wantCount == 0 {
+				wantCount = len(c.want)
+			}
+			require.Equal(t, wantCount, count)
+		})
+	}
+
+	items, _, _, err := ds.ListCertificateInventory(ctx, fleet.TeamFilter{User: admin}, fleet.CertificateInventoryListOptions{CA: ptr.Bool(true)})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "b", items[0].CommonName)
+	require.Equal(t, "CN=b", items[0].Subject)
+	require.Equal(t, "CN=Issuer", items[0].Issuer)
+	require.True(t, items[0].CA)
+	require.Equal(t, later, items[0].NotValidAfter.UTC())
+}
+
+func testHostCertificatesExpiring(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+
+	soon, later, expired := now.AddDate(0, 0, 10), now.AddDate(1, 0, 0), now.AddDate(0, 0, -1)
+	for _, h := range []*fleet.Host{h1, h2} {
+		require.NoError(t, ds.ReplaceHostCertificates(ctx, h.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+			newTestHostCertificate(h.ID, testCertSHA1A, "a", false, soon),
+			newTestHostCertificate(h.ID, testCertSHA1B, "b", true, later),
+			newTestHostCertificate(h.ID, testCertSHA1C, "c", false, expired),
+		}))
+	}
+
+	items, err := ds.ListExpiringCertificates(ctx, now, now.AddDate(0, 0, 30), 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, testCertSHA1A, items[0].SHA1)
+	require.EqualValues(t, 2, items[0].HostsCount)
+
+	// a is found on 2 hosts, not more
+	items, err = ds.ListExpiringCertificates(ctx, now, now.AddDate(0, 0, 30), 2)
+	require.NoError(t, err)
+	require.Empty(t, items)
+
+	items, err = ds.ListExpiringCertificates(ctx, now, now.AddDate(2, 0, 0), 0)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, testCertSHA1A, items[0].SHA1)
+	require.Equal(t, testCertSHA1B, items[1].SHA1)
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// hostCloudInstanceJoin joins the cloud instance of the host aliased to h, it
+// is used to load, filter and sort the hosts by cloud instance.
+const hostCloudInstanceJoin = `LEFT JOIN host_cloud_instances hci ON hci.host_id = h.id`
+
+// hostCloudInstanceSelect selects the cloud instance of the host joined with
+// hostCloudInstanceJoin, to be loaded in the Cloud* fields of fleet.Host.
+const hostCloudInstanceSelect = `
+  hci.provider AS cloud_provider,
+  hci.instance_id AS cloud_instance_id,
+  NULLIF(hci.region, '') AS cloud_region,
+  NULLIF(hci.account_id, '') AS cloud_account_id,
+  hci.tags AS cloud_tags`
+
+func (ds *Datastore) SetOrUpdateHostCloudInstance(ctx context.Context, hostID uint, instance *fleet.HostCloudInstance) error {
+	if instance == nil {
+		if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM host_cloud_instances WHERE host_id = ?`, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete host cloud instance")
+		}
+		return nil
+	}
+
+	const stmt = `
+	INSERT INTO host_cloud_instances
+		(host_id, provider, instance_id, region, account_id, tags)
+	VALUES
+		(?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		provider = VALUES(provider),
+		instance_id = VALUES(instance_id),
+		region = VALUES(region),
+		account_id = VALUES(account_id),
+		tags = VALUES(tags)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		hostID, instance.Provider, instance.InstanceID, instance.Region, instance.AccountID, instance.Tags,
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host cloud instance")
+	}
+	return nil
+}
+
+func filterHostsByCloudInstance(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.CloudProviderFilter != nil {
+		sql += ` AND hci.provider = ?`
+		params = append(params, *opt.CloudProviderFilter)
+	}
+	if opt.CloudRegionFilter != nil {
+		sql += ` AND hci.region = ?`
+		params = append(params, *opt.CloudRegionFilter)
+	}
+	if opt.CloudAccountIDFilter != nil {
+		sql += ` AND hci.account_id = ?`
+		params = append(params, *opt.CloudAccountIDFilter)
+	}
+	return sql, params
+}
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostCloudInstances(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"SetOrUpdateAndListHosts", testHostCloudInstancesSetOrUpdateAndListHosts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostCloudInstancesSetOrUpdateAndListHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	h1 := test.NewHost(t, ds, "h1", "", "h1", "h1", time.Now())
+	h2 := test.NewHost(t, ds, "h2", "", "h2", "h2", time.Now())
+	h3 := test.NewHost(t, ds, "h3", "", "h3", "h3", time.Now())
+
+	err := ds.SetOrUpdateHostCloudInstance(ctx, h1.ID, &fleet.HostCloudInstance{
+		Provider:   fleet.CloudProviderAWS,
+		InstanceID: "i-0abc",
+		Region:     "us-east-1",
+		AccountID:  "123456789012",
+		Tags:       fleet.CloudInstanceTags{"env": "staging"},
+	})
+	require.NoError(t, err)
+	err = ds.SetOrUpdateHostCloudInstance(ctx, h2.ID, &fleet.HostCloudInstance{
+		Provider:   fleet.CloudProviderGCP,
+		InstanceID: "8862617829912345678",
+		Region:     "us-central1",
+		AccountID:  "my-project",
+	})
+	require.NoError(t, err)
+	// update the tags of h1
+	err = ds.SetOrUpdateHostCloudInstance(ctx, h1.ID, &fleet.HostCloudInstance{
+		Provider:   fleet.CloudProviderAWS,
+		InstanceID: "i-0abc",
+		Region:     "us-east-1",
+		AccountID:  "123456789012",
+		Tags:       fleet.CloudInstanceTags{"env": "prod", "Name": "web-1"},
+	})
+	require.NoError(t, err)
+
+	host, err := ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.NotNil(t, host.CloudProvider)
+	require.Equal(t, fleet.CloudProviderAWS, *host.CloudProvider)
+	require.Equal(t, "i-0abc", *host.CloudInstanceID)
+	require.Equal(t, "us-east-1", *host.CloudRegion)
+	require.Equal(t, "123456789012", *host.CloudAccountID)
+	require.Equal(t, fleet.CloudInstanceTags{"env": "prod", "Name": "web-1"}, host.CloudTags)
+
+	host, err = ds.Host(ctx, h2.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.CloudProviderGCP, *host.CloudProvider)
+	require.Empty(t, host.CloudTags)
+
+	host, err = ds.Host(ctx, h3.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.CloudProvider)
+	require.Nil(t, host.CloudInstanceID)
+	require.Nil(t, host.CloudRegion)
+	require.Nil(t, host.CloudAccountID)
+	require.Nil(t, host.CloudTags)
+
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+
+	aws, gcp := fleet.CloudProviderAWS, fleet.CloudProviderGCP
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hosts, err := ds.ListHosts(ctx, filter, fleet.HostListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	for _, h := range hosts {
+		if h.ID == h1.ID {
+			require.Equal(t, "i-0abc", *h.CloudInstanceID)
+			require.Equal(t, fleet.CloudInstanceTags{"env": "prod", "Name": "web-1"}, h.CloudTags)
+		}
+	}
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{CloudProviderFilter: &gcp})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h2.ID}, hostIDs(hosts))
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{
+		CloudProviderFilter: &aws,
+		CloudRegionFilter:   ptr.String("us-east-1"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID}, hostIDs(hosts))
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{CloudRegionFilter: ptr.String("eu-west-1")})
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	count, err := ds.CountHosts(ctx, filter, fleet.HostListOptions{CloudAccountIDFilter: ptr.String("my-project")})
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// h1 is not a cloud instance anymore
+	require.NoError(t, ds.SetOrUpdateHostCloudInstance(ctx, h1.ID, nil))
+	host, err = ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.CloudProvider)
+
+	count, err = ds.CountHosts(ctx, filter, fleet.HostListOptions{CloudProviderFilter: &aws})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostCustomFieldsSelect selects the notes and custom field values of the host
+// aliased to h, to be loaded in the Notes and CustomFields fields of
+// fleet.Host.
+const hostCustomFieldsSelect = `
+  (SELECT notes FROM host_notes WHERE host_id = h.id) AS notes,
+  (SELECT JSON_OBJECTAGG(name, value) FROM host_custom_field_values WHERE host_id = h.id) AS custom_fields`
+
+func (ds *Datastore) GetHostCustomFields(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
+	return getHostCustomFieldsDB(ctx, ds.reader(ctx), hostID)
+}
+
+func getHostCustomFieldsDB(ctx context.Context, q sqlx.QueryerContext, hostID uint) (*fleet.HostCustomFields, error) {
+	res := &fleet.HostCustomFields{
+		HostID:       hostID,
+		CustomFields: fleet.HostCustomFieldValues{},
+	}
+
+	err := sqlx.GetContext(ctx, q, &res.Notes, `SELECT notes FROM host_notes WHERE host_id = ?`, hostID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ctxerr.Wrap(ctx, err, "get host notes")
+	}
+
+	var values []struct {
+		Name  string `db:"name"`
+		Value string `db:"value"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &values, `SELECT name, value FROM host_custom_field_values WHERE host_id = ?`, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host custom field values")
+	}
+	for _, v := range values {
+		res.CustomFields[v.Name] = v.Value
+	}
+	return res, nil
+}
+
+func (ds *Datastore) UpdateHostCustomFields(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
+	const (
+		upsertNotesStmt = `
+	INSERT INTO host_notes (host_id, notes) VALUES (?, ?)
+	ON DUPLICATE KEY UPDATE notes = VALUES(notes)`
+		deleteNotesStmt = `DELETE FROM host_notes WHERE host_id = ?`
+
+		upsertValueStmt = `
+	INSERT INTO host_custom_field_values (host_id, name, value) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE value = VALUES(value)`
+		deleteValueStmt = `DELETE FROM host_custom_field_values WHERE host_id = ? AND name = ?`
+	)
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if payload.Notes != nil {
+			var err error
+			if *payload.Notes == "" {
+				_, err = tx.ExecContext(ctx, deleteNotesStmt, hostID)
+			} else {
+				_, err = tx.ExecContext(ctx, upsertNotesStmt, hostID, *payload.Notes)
+			}
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "update host notes")
+			}
+		}
+
+		for name, value := range payload.CustomFields {
+			var err error
+			if value == nil {
+				_, err = tx.ExecContext(ctx, deleteValueStmt, hostID, name)
+			} else {
+				_, err = tx.ExecContext(ctx, upsertValueStmt, hostID, name, *value)
+			}
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "update host custom field value")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) DeleteHostCustomFieldValuesNotIn(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM host_custom_field_values`); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete all host custom field values")
+		}
+		return nil
+	}
+
+	stmt, args, err := sqlx.In(`DELETE FROM host_custom_field_values WHERE name NOT IN (?)`, names)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build delete host custom field values query")
+	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host custom field values")
+	}
+	return nil
+}
+
+func filterHostsByCustomField(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.CustomFieldNameFilter == nil || opt.CustomFieldValueFilter == nil {
+		return sql, params
+	}
+
+	sql += ` AND EXISTS (SELECT 1 FROM host_custom_field_values hcfv WHERE hcfv.host_id = h.id AND hcfv.name = ? AND hcfv.value = ?)`
+	params = append(params, *opt.CustomFieldNameFilter, *opt.CustomFieldValueFilter)
+
+	return sql, params
+}
+
+// hostSearchCustomFields extends the search condition added by hostSearchLike
+// so that the hosts whose notes or custom field values match are also
+// returned. It must be called right after hostSearchLike.
+func hostSearchCustomFields(sql string, params []interface{}, match string) (string, []interface{}) {
+	if len(match) == 0 {
+		return sql, params
+	}
+
+	pattern := likePattern(match)
+	// remove the closing paren and add the conditions to the list
+	sql = strings.TrimSuffix(sql, ")") + ` OR EXISTS (SELECT 1 FROM host_notes hn WHERE hn.host_id = h.id AND hn.notes LIKE ?)` +
+		` OR EXISTS (SELECT 1 FROM host_custom_field_values hcfv WHERE hcfv.host_id = h.id AND hcfv.value LIKE ?))`
+	params = append(params, pattern, pattern)
+	return sql, params
+}
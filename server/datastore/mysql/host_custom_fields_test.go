@@ -0,0 +1,190 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostCustomFields(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Update", testHostCustomFieldsUpdate},
+		{"ListAndSearch", testHostCustomFieldsListAndSearch},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostCustomFieldsUpdate(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+
+	cf, err := ds.GetHostCustomFields(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Equal(t, &fleet.HostCustomFields{HostID: h1.ID, CustomFields: fleet.HostCustomFieldValues{}}, cf)
+
+	err = ds.UpdateHostCustomFields(ctx, h1.ID, fleet.HostCustomFieldsPayload{
+		Notes:        ptr.String("some notes"),
+		CustomFields: map[string]*string{"owner": ptr.String("alice"), "asset_tag": ptr.String("A-1")},
+	})
+	require.NoError(t, err)
+	err = ds.UpdateHostCustomFields(ctx, h2.ID, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"owner": ptr.String("bob")},
+	})
+	require.NoError(t, err)
+
+	cf, err = ds.GetHostCustomFields(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "some notes", cf.Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "alice", "asset_tag": "A-1"}, cf.CustomFields)
+
+	host, err := ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Equal(t, ptr.String("some notes"), host.Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "alice", "asset_tag": "A-1"}, host.CustomFields)
+
+	host, err = ds.Host(ctx, h2.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "bob"}, host.CustomFields)
+
+	// update one field, clear another and leave the notes unchanged
+	err = ds.UpdateHostCustomFields(ctx, h1.ID, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"owner": ptr.String("carol"), "asset_tag": nil},
+	})
+	require.NoError(t, err)
+	cf, err = ds.GetHostCustomFields(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "some notes", cf.Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "carol"}, cf.CustomFields)
+
+	// clear the notes
+	err = ds.UpdateHostCustomFields(ctx, h1.ID, fleet.HostCustomFieldsPayload{Notes: ptr.String("")})
+	require.NoError(t, err)
+	host, err = ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.Notes)
+
+	// delete the values of fields that are not defined anymore
+	err = ds.UpdateHostCustomFields(ctx, h2.ID, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"cost_center": ptr.String("123")},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ds.DeleteHostCustomFieldValuesNotIn(ctx, []string{"cost_center"}))
+	cf, err = ds.GetHostCustomFields(ctx, h2.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostCustomFieldValues{"cost_center": "123"}, cf.CustomFields)
+	cf, err = ds.GetHostCustomFields(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Empty(t, cf.CustomFields)
+
+	require.NoError(t, ds.DeleteHostCustomFieldValuesNotIn(ctx, nil))
+	cf, err = ds.GetHostCustomFields(ctx, h2.ID)
+	require.NoError(t, err)
+	require.Empty(t, cf.CustomFields)
+}
+
+func testHostCustomFieldsListAndSearch(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+	h3 := test.NewHost(t, ds, "h3.local", "", "3", "uuid-3", time.Now())
+
+	err := ds.UpdateHostCustomFields(ctx, h1.ID, fleet.HostCustomFieldsPayload{
+		Notes:        ptr.String("loaner laptop"),
+		CustomFields: map[string]*string{"owner": ptr.String("alice")},
+	})
+	require.NoError(t, err)
+	err = ds.UpdateHostCustomFields(ctx, h2.ID, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"owner": ptr.String("bob"), "cost_center": ptr.String("alice-team")},
+	})
+	require.NoError(t, err)
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	// the custom fields are only loaded if requested
+	hosts, err := ds.ListHosts(ctx, filter, fleet.HostListOptions{ListOptions: fleet.ListOptions{OrderKey: "id"}})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	require.Nil(t, hosts[0].Notes)
+	require.Nil(t, hosts[0].CustomFields)
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{ListOptions: fleet.ListOptions{OrderKey: "id"}, PopulateCustomFields: true})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID, h3.ID}, hostIDs(hosts))
+	require.Equal(t, ptr.String("loaner laptop"), hosts[0].Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "alice"}, hosts[0].CustomFields)
+	require.Nil(t, hosts[1].Notes)
+	require.Equal(t, fleet.HostCustomFieldValues{"owner": "bob", "cost_center": "alice-team"}, hosts[1].CustomFields)
+	require.Nil(t, hosts[2].CustomFields)
+
+	// filter by custom field
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{
+		CustomFieldNameFilter:  ptr.String("owner"),
+		CustomFieldValueFilter: ptr.String("bob"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h2.ID}, hostIDs(hosts))
+	count, err := ds.CountHosts(ctx, filter, fleet.HostListOptions{
+		CustomFieldNameFilter:  ptr.String("owner"),
+		CustomFieldValueFilter: ptr.String("bob"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// search matches the hostname, notes and custom field values
+	cases := []struct {
+		query string
+		want  []uint
+	}{
+		{"h3", []uint{h3.ID}},
+		{"loaner", []uint{h1.ID}},
+		{"alice", []uint{h1.ID, h2.ID}},
+		{"nope", []uint{}},
+	}
+	for _, c := range cases {
+		hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{ListOptions: fleet.ListOptions{MatchQuery: c.query, OrderKey: "id"}})
+		require.NoError(t, err)
+		require.Equal(t, c.want, hostIDs(hosts), c.query)
+	}
+
+	// same in a label
+	label, err := ds.NewLabel(ctx, &fleet.Label{Name: "l1", Query: "select 1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, h1, map[uint]*bool{label.ID: ptr.Bool(true)}, time.Now(), false))
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, h2, map[uint]*bool{label.ID: ptr.Bool(true)}, time.Now(), false))
+
+	hosts, err = ds.ListHostsInLabel(ctx, filter, label.ID, fleet.HostListOptions{ListOptions: fleet.ListOptions{MatchQuery: "loaner"}, PopulateCustomFields: true})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID}, hostIDs(hosts))
+	require.Equal(t, ptr.String("loaner laptop"), hosts[0].Notes)
+
+	hosts, err = ds.ListHostsInLabel(ctx, filter, label.ID, fleet.HostListOptions{
+		CustomFieldNameFilter:  ptr.String("owner"),
+		CustomFieldValueFilter: ptr.String("alice"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID}, hostIDs(hosts))
+}
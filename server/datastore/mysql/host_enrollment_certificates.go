@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) GetHostEnrollmentCertificateBySHA256(ctx context.Context, sha256 string) (*fleet.HostEnrollmentCertificate, error) {
+	// the certificates are kept when their host is deleted, in which case the
+	// host uuid is empty.
+	const stmt = `
+	SELECT
+		hec.id, hec.host_id, hec.sha256, hec.serial_number, hec.subject, hec.issuer,
+		hec.not_valid_after, hec.revoked_at, hec.created_at,
+		COALESCE(h.uuid, '') AS host_uuid, h.team_id AS host_team_id
+	FROM host_enrollment_certificates hec
+		LEFT JOIN hosts h ON h.id = hec.host_id
+	WHERE hec.sha256 = ?`
+
+	var cert fleet.HostEnrollmentCertificate
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &cert, stmt, sha256); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostEnrollmentCertificate").WithName(sha256))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host enrollment certificate")
+	}
+	return &cert, nil
+}
+
+func (ds *Datastore) RecordHostEnrollmentCertificate(ctx context.Context, cert *fleet.HostEnrollmentCertificate) error {
+	// the host_id is only updated when the certificate was bound to a deleted
+	// host, the caller checks that it is not bound to another existing host.
+	const stmt = `
+	INSERT INTO host_enrollment_certificates
+		(host_id, sha256, serial_number, subject, issuer, not_valid_after)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		host_id = VALUES(host_id)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, cert.HostID, cert.SHA256, cert.SerialNumber,
+		cert.Subject, cert.Issuer, cert.NotValidAfter); err != nil {
+		return ctxerr.Wrap(ctx, err, "record host enrollment certificate")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListHostEnrollmentCertificates(ctx context.Context, hostID uint) ([]*fleet.HostEnrollmentCertificate, error) {
+	const stmt = `
+	SELECT
+		id, host_id, sha256, serial_number, subject, issuer,
+		not_valid_after, revoked_at, created_at
+	FROM host_enrollment_certificates
+	WHERE host_id = ?
+	ORDER BY created_at DESC, id DESC`
+
+	var certs []*fleet.HostEnrollmentCertificate
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &certs, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host enrollment certificates")
+	}
+	return certs, nil
+}
+
+func (ds *Datastore) RevokeHostEnrollmentCertificates(ctx context.Context, hostID uint) (int, error) {
+	const revokeStmt = `
+	UPDATE host_enrollment_certificates
+	SET revoked_at = CURRENT_TIMESTAMP
+	WHERE host_id = ? AND revoked_at IS NULL`
+
+	const clearNodeKeysStmt = `
+	UPDATE hosts
+	SET node_key = NULL, orbit_node_key = NULL
+	WHERE id = ?`
+
+	var revoked int
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		res, err := tx.ExecContext(ctx, revokeStmt, hostID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "revoke host enrollment certificates")
+		}
+		n, _ := res.RowsAffected()
+		revoked = int(n)
+		if revoked == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, clearNodeKeysStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "clear host node keys")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
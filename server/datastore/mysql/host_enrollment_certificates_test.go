@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostEnrollmentCertificates(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1-uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host1.ID}))
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2-uuid", time.Now())
+
+	notAfter := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	newCert := func(hostID uint, sha string) *fleet.HostEnrollmentCertificate {
+		return &fleet.HostEnrollmentCertificate{
+			HostID:        hostID,
+			SHA256:        sha,
+			SerialNumber:  "01",
+			Subject:       "CN=" + sha,
+			Issuer:        "CN=ca",
+			NotValidAfter: notAfter,
+		}
+	}
+
+	_, err = ds.GetHostEnrollmentCertificateBySHA256(ctx, "aaa")
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.RecordHostEnrollmentCertificate(ctx, newCert(host1.ID, "aaa")))
+	// recording it again is a no-op
+	require.NoError(t, ds.RecordHostEnrollmentCertificate(ctx, newCert(host1.ID, "aaa")))
+	require.NoError(t, ds.RecordHostEnrollmentCertificate(ctx, newCert(host1.ID, "bbb")))
+	require.NoError(t, ds.RecordHostEnrollmentCertificate(ctx, newCert(host2.ID, "ccc")))
+
+	cert, err := ds.GetHostEnrollmentCertificateBySHA256(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, host1.ID, cert.HostID)
+	require.Equal(t, "host1-uuid", cert.HostUUID)
+	require.Equal(t, &team.ID, cert.HostTeamID)
+	require.Equal(t, "CN=aaa", cert.Subject)
+	require.Equal(t, notAfter, cert.NotValidAfter)
+	require.Nil(t, cert.RevokedAt)
+
+	certs, err := ds.ListHostEnrollmentCertificates(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	certs, err = ds.ListHostEnrollmentCertificates(ctx, host2.ID)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+
+	// revoking the certificates clears the node keys of the host
+	n, err := ds.RevokeHostEnrollmentCertificates(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	cert, err = ds.GetHostEnrollmentCertificateBySHA256(ctx, "aaa")
+	require.NoError(t, err)
+	require.NotNil(t, cert.RevokedAt)
+	h, err := ds.Host(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Nil(t, h.NodeKey)
+	require.Nil(t, h.OrbitNodeKey)
+
+	// nothing left to revoke, the node keys are kept
+	n, err = ds.RevokeHostEnrollmentCertificates(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	h, err = ds.Host(ctx, host2.ID)
+	require.NoError(t, err)
+	require.Equal(t, ptr.String("host2key"), h.NodeKey)
+
+	// the certificates are kept when the host is deleted
+	require.NoError(t, ds.DeleteHost(ctx, host2.ID))
+	cert, err = ds.GetHostEnrollmentCertificateBySHA256(ctx, "ccc")
+	require.NoError(t, err)
+	require.Empty(t, cert.HostUUID)
+	require.Nil(t, cert.HostTeamID)
+}
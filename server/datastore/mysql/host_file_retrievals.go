@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var hostFileRetrievalSelectColumns = fmt.Sprintf(`
+	id,
+	host_id,
+	execution_id,
+	path,
+	notify_end_user,
+	user_id,
+	CASE
+		WHEN completed_at IS NULL THEN '%s'
+		WHEN COALESCE(error, '') <> '' THEN '%s'
+		ELSE '%s'
+	END AS status,
+	COALESCE(error, '') AS error,
+	size,
+	created_at,
+	completed_at
+`, fleet.HostFileRetrievalStatusPending, fleet.HostFileRetrievalStatusFailed, fleet.HostFileRetrievalStatusCompleted)
+
+func (ds *Datastore) NewHostFileRetrieval(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error) {
+	const stmt = `
+  INSERT INTO host_file_retrievals (
+    host_id,
+    execution_id,
+    path,
+    notify_end_user,
+    user_id
+  ) VALUES (?, ?, ?, ?, ?)`
+
+	execID := uuid.New().String()
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		payload.HostID,
+		execID,
+		payload.Path,
+		payload.NotifyEndUser,
+		payload.UserID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new host file retrieval")
+	}
+	return ds.getHostFileRetrieval(ctx, ds.writer(ctx), execID)
+}
+
+func (ds *Datastore) GetHostFileRetrieval(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error) {
+	return ds.getHostFileRetrieval(ctx, ds.reader(ctx), execID)
+}
+
+func (ds *Datastore) getHostFileRetrieval(ctx context.Context, q sqlx.QueryerContext, execID string) (*fleet.HostFileRetrieval, error) {
+	stmt := `SELECT ` + hostFileRetrievalSelectColumns + ` FROM host_file_retrievals WHERE execution_id = ?`
+
+	var fr fleet.HostFileRetrieval
+	if err := sqlx.GetContext(ctx, q, &fr, stmt, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostFileRetrieval").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host file retrieval")
+	}
+	return &fr, nil
+}
+
+func (ds *Datastore) GetHostFileRetrievalData(ctx context.Context, execID string) ([]byte, error) {
+	var data []byte
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &data, `SELECT data FROM host_file_retrievals WHERE execution_id = ?`, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostFileRetrieval").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host file retrieval data")
+	}
+	return data, nil
+}
+
+func (ds *Datastore) ListHostFileRetrievals(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+	stmt := `SELECT ` + hostFileRetrievalSelectColumns + ` FROM host_file_retrievals WHERE host_id = ? ORDER BY created_at DESC, id DESC`
+
+	var results []*fleet.HostFileRetrieval
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host file retrievals")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) ListPendingHostFileRetrievals(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+	stmt := `
+  SELECT ` + hostFileRetrievalSelectColumns + `
+  FROM
+    host_file_retrievals
+  WHERE
+    host_id = ? AND
+    completed_at IS NULL AND
+    created_at >= DATE_SUB(NOW(), INTERVAL ? SECOND)
+  ORDER BY
+    created_at ASC, id ASC`
+
+	seconds := int(fleet.MaxHostFileRetrievalPendingAge.Seconds())
+	var results []*fleet.HostFileRetrieval
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID, seconds); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list pending host file retrievals")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) SetHostFileRetrievalResult(ctx context.Context, result *fleet.HostFileRetrievalResultPayload) (*fleet.HostFileRetrieval, error) {
+	const updStmt = `
+  UPDATE host_file_retrievals SET
+    error = ?,
+    data = ?,
+    size = ?,
+    completed_at = CURRENT_TIMESTAMP
+  WHERE
+    host_id = ? AND
+    execution_id = ? AND
+    completed_at IS NULL`
+
+	var data []byte
+	if result.Error == "" {
+		data = result.Data
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, updStmt,
+		result.Error,
+		data,
+		len(data),
+		result.HostID,
+		result.ExecutionID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host file retrieval result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// the file retrieval does not exist for that host or already has a
+		// result, ignore the new one.
+		return nil, nil
+	}
+	return ds.getHostFileRetrieval(ctx, ds.writer(ctx), result.ExecutionID)
+}
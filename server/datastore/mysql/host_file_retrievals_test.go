@@ -0,0 +1,162 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostFileRetrievals(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"RequestAndResult", testHostFileRetrievalsRequestAndResult},
+		{"ListPending", testHostFileRetrievalsListPending},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostFileRetrievalsRequestAndResult(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	req1, err := ds.NewHostFileRetrieval(ctx, &fleet.HostFileRetrievalPayload{
+		HostID:        1,
+		Path:          "/var/log/system.log",
+		NotifyEndUser: true,
+		UserID:        &user.ID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, req1.ExecutionID)
+	require.Equal(t, uint(1), req1.HostID)
+	require.Equal(t, "/var/log/system.log", req1.Path)
+	require.True(t, req1.NotifyEndUser)
+	require.Equal(t, user.ID, *req1.UserID)
+	require.Equal(t, fleet.HostFileRetrievalStatusPending, req1.Status)
+	require.Nil(t, req1.CompletedAt)
+
+	req2, err := ds.NewHostFileRetrieval(ctx, &fleet.HostFileRetrievalPayload{
+		HostID: 2,
+		Path:   `C:\Windows\System32\drivers\etc\hosts`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, `C:\Windows\System32\drivers\etc\hosts`, req2.Path)
+	require.False(t, req2.NotifyEndUser)
+	require.Nil(t, req2.UserID)
+
+	_, err = ds.GetHostFileRetrieval(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.GetHostFileRetrievalData(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+
+	// a result from a different host is ignored
+	fr, err := ds.SetHostFileRetrievalResult(ctx, &fleet.HostFileRetrievalResultPayload{
+		HostID:      2,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("content"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, fr)
+
+	fr, err = ds.SetHostFileRetrievalResult(ctx, &fleet.HostFileRetrievalResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("content"),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, fr)
+	require.Equal(t, fleet.HostFileRetrievalStatusCompleted, fr.Status)
+	require.EqualValues(t, 7, fr.Size)
+	require.NotNil(t, fr.CompletedAt)
+
+	data, err := ds.GetHostFileRetrievalData(ctx, req1.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), data)
+
+	// a second result is ignored
+	fr, err = ds.SetHostFileRetrievalResult(ctx, &fleet.HostFileRetrievalResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("other content"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, fr)
+	data, err = ds.GetHostFileRetrievalData(ctx, req1.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), data)
+
+	// an error result does not store data
+	fr, err = ds.SetHostFileRetrievalResult(ctx, &fleet.HostFileRetrievalResultPayload{
+		HostID:      2,
+		ExecutionID: req2.ExecutionID,
+		Error:       "file does not exist",
+		Data:        []byte("partial"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostFileRetrievalStatusFailed, fr.Status)
+	require.Equal(t, "file does not exist", fr.Error)
+	require.Zero(t, fr.Size)
+	data, err = ds.GetHostFileRetrievalData(ctx, req2.ExecutionID)
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	list, err := ds.ListHostFileRetrievals(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, req1.ExecutionID, list[0].ExecutionID)
+	list, err = ds.ListHostFileRetrievals(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func testHostFileRetrievalsListPending(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	newReq := func(hostID uint) *fleet.HostFileRetrieval {
+		fr, err := ds.NewHostFileRetrieval(ctx, &fleet.HostFileRetrievalPayload{
+			HostID: hostID,
+			Path:   "/etc/hosts",
+		})
+		require.NoError(t, err)
+		return fr
+	}
+
+	pending, err := ds.ListPendingHostFileRetrievals(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	fr1 := newReq(1)
+	fr2 := newReq(1)
+	fr3 := newReq(1)
+	newReq(2)
+
+	// fr2 is too old to still be sent to the host
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_file_retrievals SET created_at = ? WHERE execution_id = ?`,
+			time.Now().Add(-fleet.MaxHostFileRetrievalPendingAge-time.Hour), fr2.ExecutionID)
+		return err
+	})
+	// fr3 is completed
+	_, err = ds.SetHostFileRetrievalResult(ctx, &fleet.HostFileRetrievalResultPayload{HostID: 1, ExecutionID: fr3.ExecutionID, Data: []byte("x")})
+	require.NoError(t, err)
+
+	pending, err = ds.ListPendingHostFileRetrievals(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, fr1.ExecutionID, pending[0].ExecutionID)
+	require.Equal(t, fleet.HostFileRetrievalStatusPending, pending[0].Status)
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostGroupSelectStmt selects the host groups, with the count of member hosts
+// that are visible with the team filter. The host groups table is aliased to
+// hg.
+const hostGroupSelectStmt = `
+	SELECT
+		hg.id,
+		hg.name,
+		hg.description,
+		hg.author_id,
+		hg.created_at,
+		hg.updated_at,
+		(
+			SELECT COUNT(*)
+			FROM host_group_members hgm
+			JOIN hosts h ON h.id = hgm.host_id
+			WHERE hgm.host_group_id = hg.id AND %s
+		) AS host_count
+	FROM host_groups hg
+	WHERE TRUE
+`
+
+func (ds *Datastore) NewHostGroup(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
+	const stmt = `INSERT INTO host_groups (name, description, author_id) VALUES (?, ?, ?)`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, group.Name, group.Description, group.AuthorID)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("HostGroup", group.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "inserting host group")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return ds.hostGroupByID(ctx, ds.writer(ctx), fleet.TeamFilter{}, uint(id))
+}
+
+func (ds *Datastore) HostGroup(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
+	return ds.hostGroupByID(ctx, ds.reader(ctx), filter, id)
+}
+
+func (ds *Datastore) hostGroupByID(ctx context.Context, q sqlx.QueryerContext, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
+	stmt := fmt.Sprintf(hostGroupSelectStmt, ds.whereFilterHostsByTeams(filter, "h")) + ` AND hg.id = ?`
+
+	var group fleet.HostGroup
+	if err := sqlx.GetContext(ctx, q, &group, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostGroup").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting host group by id")
+	}
+	return &group, nil
+}
+
+func (ds *Datastore) ListHostGroups(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.HostGroup, error) {
+	stmt := fmt.Sprintf(hostGroupSelectStmt, ds.whereFilterHostsByTeams(filter, "h"))
+	stmt, params := searchLike(stmt, nil, opt.MatchQuery, "hg.name")
+	if opt.OrderKey == "" {
+		opt.OrderKey = "name"
+	}
+	stmt, params = appendListOptionsWithCursorToSQL(stmt, params, &opt)
+
+	groups := []*fleet.HostGroup{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &groups, stmt, params...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing host groups")
+	}
+	return groups, nil
+}
+
+func (ds *Datastore) SaveHostGroup(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
+	const stmt = `UPDATE host_groups SET name = ?, description = ? WHERE id = ?`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, group.Name, group.Description, group.ID); err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("HostGroup", group.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "updating host group")
+	}
+	return ds.hostGroupByID(ctx, ds.writer(ctx), fleet.TeamFilter{}, group.ID)
+}
+
+func (ds *Datastore) DeleteHostGroup(ctx context.Context, id uint) error {
+	// the members of the group are deleted by the foreign key.
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM host_groups WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host group")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostGroup").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) AddHostsToHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	const stmt = `INSERT IGNORE INTO host_group_members (host_group_id, host_id) VALUES %s`
+	const batchSize = 10000
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		args := make([]any, 0, 2*len(batch))
+		for _, hostID := range batch {
+			args = append(args, groupID, hostID)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?),", len(batch)), ",")
+		if _, err := ds.writer(ctx).ExecContext(ctx, fmt.Sprintf(stmt, values), args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "adding hosts to host group")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) RemoveHostsFromHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	const batchSize = 50000
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		stmt, args, err := sqlx.In(`DELETE FROM host_group_members WHERE host_group_id = ? AND host_id IN (?)`, groupID, batch)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "building query to remove hosts from host group")
+		}
+		if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "removing hosts from host group")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostGroups(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testHostGroupsCRUD},
+		{"Members", testHostGroupsMembers},
+		{"Targets", testHostGroupsTargets},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostGroupsCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	g1, err := ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "g1", Description: "desc", AuthorID: &user.ID})
+	require.NoError(t, err)
+	require.NotZero(t, g1.ID)
+	require.Equal(t, "g1", g1.Name)
+	require.Equal(t, "desc", g1.Description)
+	require.Equal(t, user.ID, *g1.AuthorID)
+	require.Zero(t, g1.HostCount)
+
+	_, err = ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "g1"})
+	var aeErr fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aeErr)
+
+	g2, err := ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "another"})
+	require.NoError(t, err)
+
+	groups, err := ds.ListHostGroups(ctx, fleet.TeamFilter{User: test.UserAdmin}, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, g2.ID, groups[0].ID) // ordered by name
+	require.Equal(t, g1.ID, groups[1].ID)
+
+	groups, err = ds.ListHostGroups(ctx, fleet.TeamFilter{User: test.UserAdmin}, fleet.ListOptions{MatchQuery: "g1"})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, g1.ID, groups[0].ID)
+
+	g1.Name = "renamed"
+	g1.Description = ""
+	g1, err = ds.SaveHostGroup(ctx, g1)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", g1.Name)
+	require.Empty(t, g1.Description)
+
+	g2.Name = "renamed"
+	_, err = ds.SaveHostGroup(ctx, g2)
+	require.ErrorAs(t, err, &aeErr)
+
+	require.NoError(t, ds.DeleteHostGroup(ctx, g1.ID))
+	_, err = ds.HostGroup(ctx, fleet.TeamFilter{User: test.UserAdmin}, g1.ID)
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.DeleteHostGroup(ctx, g1.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the author is cleared when the user is deleted
+	g3, err := ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "g3", AuthorID: &user.ID})
+	require.NoError(t, err)
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+	g3, err = ds.HostGroup(ctx, fleet.TeamFilter{User: test.UserAdmin}, g3.ID)
+	require.NoError(t, err)
+	require.Nil(t, g3.AuthorID)
+}
+
+func testHostGroupsMembers(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+	h3 := test.NewHost(t, ds, "h3.local", "", "3", "uuid-3", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{h3.ID}))
+	h3.HardwareSerial = "serial-3"
+	require.NoError(t, ds.UpdateHost(ctx, h3))
+
+	adminFilter := fleet.TeamFilter{User: test.UserAdmin}
+	teamFilter := fleet.TeamFilter{User: &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}, IncludeObserver: true}
+
+	ids, err := ds.HostIDsByIdentifiers(ctx, adminFilter, []string{"h1.local", "uuid-2", "serial-3", "unknown"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []uint{h1.ID, h2.ID, h3.ID}, ids)
+	ids, err = ds.HostIDsByIdentifiers(ctx, teamFilter, []string{"h1.local", "uuid-2", "serial-3"})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h3.ID}, ids)
+
+	g, err := ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "g"})
+	require.NoError(t, err)
+
+	require.NoError(t, ds.AddHostsToHostGroup(ctx, g.ID, []uint{h1.ID, h2.ID, h3.ID}))
+	// adding again is a no-op
+	require.NoError(t, ds.AddHostsToHostGroup(ctx, g.ID, []uint{h1.ID}))
+
+	g, err = ds.HostGroup(ctx, adminFilter, g.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(3), g.HostCount)
+	// the host count only includes the hosts visible with the filter
+	g, err = ds.HostGroup(ctx, teamFilter, g.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), g.HostCount)
+	groups, err := ds.ListHostGroups(ctx, teamFilter, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, uint(1), groups[0].HostCount)
+
+	hosts, err := ds.ListHosts(ctx, adminFilter, fleet.HostListOptions{HostGroupIDFilter: &g.ID})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+
+	require.NoError(t, ds.RemoveHostsFromHostGroup(ctx, g.ID, []uint{h1.ID, h3.ID}))
+	hosts, err = ds.ListHosts(ctx, adminFilter, fleet.HostListOptions{HostGroupIDFilter: &g.ID})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Equal(t, h2.ID, hosts[0].ID)
+
+	// deleting a host removes it from its groups
+	require.NoError(t, ds.DeleteHost(ctx, h2.ID))
+	g, err = ds.HostGroup(ctx, adminFilter, g.ID)
+	require.NoError(t, err)
+	require.Zero(t, g.HostCount)
+}
+
+func testHostGroupsTargets(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+	h3 := test.NewHost(t, ds, "h3.local", "", "3", "uuid-3", time.Now())
+
+	g, err := ds.NewHostGroup(ctx, &fleet.HostGroup{Name: "g"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToHostGroup(ctx, g.ID, []uint{h1.ID, h2.ID}))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	ids, err := ds.HostIDsInTargets(ctx, filter, fleet.HostTargets{HostGroupIDs: []uint{g.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID}, ids)
+
+	ids, err = ds.HostIDsInTargets(ctx, filter, fleet.HostTargets{HostGroupIDs: []uint{g.ID}, HostIDs: []uint{h3.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID, h3.ID}, ids)
+
+	metrics, err := ds.CountHostsInTargets(ctx, filter, fleet.HostTargets{HostGroupIDs: []uint{g.ID}}, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, uint(2), metrics.TotalHosts)
+
+	// the host group target is stored with the campaign
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	q, err := ds.NewQuery(ctx, &fleet.Query{Name: "q", Query: "select 1", Logging: fleet.LoggingSnapshot})
+	require.NoError(t, err)
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{QueryID: q.ID, Status: fleet.QueryWaiting, UserID: user.ID})
+	require.NoError(t, err)
+	_, err = ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+		Type:                       fleet.TargetHostGroup,
+		DistributedQueryCampaignID: campaign.ID,
+		TargetID:                   g.ID,
+	})
+	require.NoError(t, err)
+	targets, err := ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.Equal(t, []uint{g.ID}, targets.HostGroupIDs)
+}
@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostHealthScoreJoin joins the health score of the host aliased to h, it is
+// used to filter and sort the hosts by health score.
+const hostHealthScoreJoin = `LEFT JOIN host_health_scores hhs ON hhs.host_id = h.id`
+
+func (ds *Datastore) ListHostHealthScoreInputs(ctx context.Context, afterHostID uint, recoveredSince time.Time, limit int) ([]*fleet.HostHealthScoreInputs, error) {
+	// the exploitable vulnerabilities are the CVEs of the host's software and
+	// operating system that are in the CISA catalog of known exploited
+	// vulnerabilities.
+	const stmt = `
+	SELECT
+		h.id AS host_id,
+		h.platform,
+		COALESCE(hst.seen_time, h.created_at) AS seen_time,
+		hmdm.enrolled AS mdm_enrolled,
+		(
+			SELECT COUNT(*)
+			FROM policy_membership pm
+			JOIN policies p ON p.id = pm.policy_id
+			WHERE pm.host_id = h.id AND pm.passes = 0 AND p.critical = 0
+		) AS failing_policies,
+		(
+			SELECT COUNT(*)
+			FROM policy_membership pm
+			JOIN policies p ON p.id = pm.policy_id
+			WHERE pm.host_id = h.id AND pm.passes = 0 AND p.critical = 1
+		) AS failing_critical_policies,
+		(
+			(
+				SELECT COUNT(DISTINCT sc.cve)
+				FROM host_software hs
+				JOIN software_cve sc ON sc.software_id = hs.software_id
+				JOIN cve_meta cm ON cm.cve = sc.cve
+				WHERE hs.host_id = h.id AND cm.cisa_known_exploit = 1
+			) + (
+				SELECT COUNT(DISTINCT osv.cve)
+				FROM host_operating_system hos
+				JOIN operating_system_vulnerabilities osv ON osv.operating_system_id = hos.os_id
+				JOIN cve_meta cm ON cm.cve = osv.cve
+				WHERE hos.host_id = h.id AND cm.cisa_known_exploit = 1
+			)
+		) AS exploitable_vulnerabilities,
+		(
+			SELECT COUNT(*)
+			FROM host_activities ha
+			JOIN activities a ON a.id = ha.activity_id
+			WHERE ha.host_id = h.id AND a.activity_type = ? AND a.created_at >= ?
+		) AS service_recoveries,
+		(
+			(SELECT COUNT(*) FROM host_mdm_apple_profiles WHERE host_uuid = h.uuid AND status = ?) +
+			(SELECT COUNT(*) FROM host_mdm_windows_profiles WHERE host_uuid = h.uuid AND status = ?)
+		) AS failed_mdm_profiles
+	FROM
+		hosts h
+		LEFT JOIN host_seen_times hst ON hst.host_id = h.id
+		LEFT JOIN host_mdm hmdm ON hmdm.host_id = h.id
+	WHERE
+		h.id > ?
+	ORDER BY
+		h.id
+	LIMIT ?`
+
+	args := []interface{}{
+		fleet.ActivityTypeFleetdServiceRecovered{}.ActivityName(), recoveredSince,
+		fleet.MDMDeliveryFailed, fleet.MDMDeliveryFailed,
+		afterHostID, limit,
+	}
+	var inputs []*fleet.HostHealthScoreInputs
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &inputs, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host health score inputs")
+	}
+	return inputs, nil
+}
+
+func (ds *Datastore) UpsertHostHealthScores(ctx context.Context, scores []*fleet.HostHealthScore) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	const stmt = `
+	INSERT INTO host_health_scores
+		(host_id, score, failing_policies_penalty, vulnerabilities_penalty, agent_penalty, mdm_penalty)
+	VALUES
+		%s
+	ON DUPLICATE KEY UPDATE
+		score = VALUES(score),
+		failing_policies_penalty = VALUES(failing_policies_penalty),
+		vulnerabilities_penalty = VALUES(vulnerabilities_penalty),
+		agent_penalty = VALUES(agent_penalty),
+		mdm_penalty = VALUES(mdm_penalty),
+		updated_at = CURRENT_TIMESTAMP`
+
+	const batchSize = 1000
+	for i := 0; i < len(scores); i += batchSize {
+		end := i + batchSize
+		if end > len(scores) {
+			end = len(scores)
+		}
+		batch := scores[i:end]
+
+		args := make([]interface{}, 0, len(batch)*6)
+		for _, s := range batch {
+			args = append(args, s.HostID, s.Score, s.FailingPoliciesPenalty, s.VulnerabilitiesPenalty, s.AgentPenalty, s.MDMPenalty)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?),", len(batch)), ",")
+		if _, err := ds.writer(ctx).ExecContext(ctx, fmt.Sprintf(stmt, values), args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert host health scores")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostHealthScores(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+	if len(hostIDs) == 0 {
+		return nil, nil
+	}
+
+	stmt, args, err := sqlx.In(`
+	SELECT
+		host_id, score, failing_policies_penalty, vulnerabilities_penalty, agent_penalty, mdm_penalty, updated_at
+	FROM
+		host_health_scores
+	WHERE
+		host_id IN (?)`, hostIDs)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build get host health scores query")
+	}
+
+	var scores []*fleet.HostHealthScore
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &scores, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host health scores")
+	}
+	res := make(map[uint]*fleet.HostHealthScore, len(scores))
+	for _, s := range scores {
+		res[s.HostID] = s
+	}
+	return res, nil
+}
+
+func filterHostsByHealthScore(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.MaxHealthScoreFilter == nil {
+		return sql, params
+	}
+
+	sql += ` AND hhs.score <= ?`
+	params = append(params, *opt.MaxHealthScoreFilter)
+
+	return sql, params
+}
@@ -0,0 +1,186 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostHealthScores(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ListInputs", testHostHealthScoresListInputs},
+		{"UpsertAndListHosts", testHostHealthScoresUpsertAndListHosts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostHealthScoresListInputs(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	mac := test.NewHost(t, ds, "mac", "", "mac", "mac", now)
+	win := test.NewHost(t, ds, "win", "", "win", "win", now.Add(-48*time.Hour), test.WithPlatform("windows"))
+	linux := test.NewHost(t, ds, "linux", "", "linux", "linux", now, test.WithPlatform("ubuntu"))
+
+	// the mac fails a policy and a critical policy
+	pol1, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "pol1", Query: "select 1"})
+	require.NoError(t, err)
+	pol2, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "pol2", Query: "select 1", Critical: true})
+	require.NoError(t, err)
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, mac, map[uint]*bool{pol1.ID: ptr.Bool(false), pol2.ID: ptr.Bool(false)}, now, false))
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, linux, map[uint]*bool{pol1.ID: ptr.Bool(true), pol2.ID: ptr.Bool(false)}, now, false))
+
+	// the mac has software with a known exploited vulnerability and another one
+	_, err = ds.UpdateHostSoftware(ctx, mac.ID, []fleet.Software{{Name: "foo", Version: "1.0", Source: "apps"}})
+	require.NoError(t, err)
+	require.NoError(t, ds.LoadHostSoftware(ctx, mac, false))
+	require.Len(t, mac.Software, 1)
+	for _, cve := range []string{"CVE-2024-0001", "CVE-2024-0002"} {
+		_, err = ds.InsertSoftwareVulnerability(ctx, fleet.SoftwareVulnerability{SoftwareID: mac.Software[0].ID, CVE: cve}, fleet.NVDSource)
+		require.NoError(t, err)
+	}
+	require.NoError(t, ds.InsertCVEMeta(ctx, []fleet.CVEMeta{
+		{CVE: "CVE-2024-0001", CISAKnownExploit: ptr.Bool(true)},
+		{CVE: "CVE-2024-0002", CISAKnownExploit: ptr.Bool(false)},
+	}))
+
+	// fleetd was recovered twice on the windows host, once before the window
+	for i := 0; i < 2; i++ {
+		require.NoError(t, ds.NewActivity(ctx, nil, fleet.ActivityTypeFleetdServiceRecovered{
+			HostID:      win.ID,
+			Reason:      fleet.HostServiceRecoveryCrash,
+			RecoveredAt: now,
+		}))
+	}
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE activities SET created_at = ? ORDER BY id LIMIT 1`, now.Add(-30*24*time.Hour))
+		return err
+	})
+
+	// the windows host is not enrolled in MDM and has a failed profile
+	require.NoError(t, ds.SetOrUpdateMDMData(ctx, win.ID, false, false, "https://example.com", false, fleet.WellKnownMDMFleet, ""))
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO host_mdm_windows_profiles (host_uuid, profile_uuid, status, command_uuid) VALUES (?, 'w1', ?, 'c1')`,
+			win.UUID, fleet.MDMDeliveryFailed)
+		return err
+	})
+
+	inputs, err := ds.ListHostHealthScoreInputs(ctx, 0, now.Add(-fleet.HostHealthScoreRecoveryWindow), 10)
+	require.NoError(t, err)
+	require.Len(t, inputs, 3)
+
+	require.Equal(t, mac.ID, inputs[0].HostID)
+	require.Equal(t, "darwin", inputs[0].Platform)
+	require.Equal(t, 1, inputs[0].FailingPolicies)
+	require.Equal(t, 1, inputs[0].FailingCriticalPolicies)
+	require.Equal(t, 1, inputs[0].ExploitableVulnerabilities)
+	require.Zero(t, inputs[0].ServiceRecoveries)
+	require.Nil(t, inputs[0].MDMEnrolled)
+	require.Zero(t, inputs[0].FailedMDMProfiles)
+	require.WithinDuration(t, now, inputs[0].SeenTime, time.Second)
+
+	require.Equal(t, win.ID, inputs[1].HostID)
+	require.Zero(t, inputs[1].FailingPolicies)
+	require.Zero(t, inputs[1].ExploitableVulnerabilities)
+	require.Equal(t, 1, inputs[1].ServiceRecoveries)
+	require.NotNil(t, inputs[1].MDMEnrolled)
+	require.False(t, *inputs[1].MDMEnrolled)
+	require.Equal(t, 1, inputs[1].FailedMDMProfiles)
+	require.WithinDuration(t, now.Add(-48*time.Hour), inputs[1].SeenTime, time.Second)
+
+	require.Equal(t, linux.ID, inputs[2].HostID)
+	require.Zero(t, inputs[2].FailingPolicies)
+	require.Equal(t, 1, inputs[2].FailingCriticalPolicies)
+
+	// paginate by host ID
+	inputs, err = ds.ListHostHealthScoreInputs(ctx, mac.ID, now.Add(-fleet.HostHealthScoreRecoveryWindow), 1)
+	require.NoError(t, err)
+	require.Len(t, inputs, 1)
+	require.Equal(t, win.ID, inputs[0].HostID)
+
+	inputs, err = ds.ListHostHealthScoreInputs(ctx, linux.ID, now.Add(-fleet.HostHealthScoreRecoveryWindow), 10)
+	require.NoError(t, err)
+	require.Empty(t, inputs)
+}
+
+func testHostHealthScoresUpsertAndListHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	h1 := test.NewHost(t, ds, "h1", "", "h1", "h1", time.Now())
+	h2 := test.NewHost(t, ds, "h2", "", "h2", "h2", time.Now())
+	h3 := test.NewHost(t, ds, "h3", "", "h3", "h3", time.Now())
+
+	scores, err := ds.GetHostHealthScores(ctx, []uint{h1.ID})
+	require.NoError(t, err)
+	require.Empty(t, scores)
+
+	require.NoError(t, ds.UpsertHostHealthScores(ctx, nil))
+	err = ds.UpsertHostHealthScores(ctx, []*fleet.HostHealthScore{
+		{HostID: h1.ID, Score: 80, FailingPoliciesPenalty: 20},
+		{HostID: h2.ID, Score: 100},
+	})
+	require.NoError(t, err)
+	err = ds.UpsertHostHealthScores(ctx, []*fleet.HostHealthScore{
+		{HostID: h2.ID, Score: 55, VulnerabilitiesPenalty: 30, AgentPenalty: 15},
+	})
+	require.NoError(t, err)
+
+	scores, err = ds.GetHostHealthScores(ctx, []uint{h1.ID, h2.ID, h3.ID})
+	require.NoError(t, err)
+	require.Len(t, scores, 2)
+	require.Equal(t, 80, scores[h1.ID].Score)
+	require.Equal(t, 20, scores[h1.ID].FailingPoliciesPenalty)
+	require.Equal(t, 55, scores[h2.ID].Score)
+	require.Equal(t, 30, scores[h2.ID].VulnerabilitiesPenalty)
+	require.Equal(t, 15, scores[h2.ID].AgentPenalty)
+	require.Zero(t, scores[h2.ID].MDMPenalty)
+
+	host, err := ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.NotNil(t, host.HealthScore)
+	require.Equal(t, 80, *host.HealthScore)
+	host, err = ds.Host(ctx, h3.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.HealthScore)
+
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hosts, err := ds.ListHosts(ctx, filter, fleet.HostListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "health_score", OrderDirection: fleet.OrderDescending},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID, h3.ID}, hostIDs(hosts))
+	require.Equal(t, 80, *hosts[0].HealthScore)
+	require.Nil(t, hosts[2].HealthScore)
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{MaxHealthScoreFilter: ptr.Int(60)})
+	require.NoError(t, err)
+	require.Equal(t, []uint{h2.ID}, hostIDs(hosts))
+
+	count, err := ds.CountHosts(ctx, filter, fleet.HostListOptions{MaxHealthScoreFilter: ptr.Int(80)})
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
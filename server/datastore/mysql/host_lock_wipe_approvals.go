@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string, userID uint) error {
+	const stmt = `
+		INSERT INTO host_lock_wipe_approvals
+			(host_id, action, requested_by_user_id, created_at)
+		VALUES
+			(?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE
+			requested_by_user_id = VALUES(requested_by_user_id),
+			created_at = CURRENT_TIMESTAMP`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, action, userID); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host lock/wipe approval request")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string) (*fleet.HostLockWipeApprovalRequest, error) {
+	const stmt = `
+		SELECT
+			host_id,
+			action,
+			requested_by_user_id,
+			created_at
+		FROM
+			host_lock_wipe_approvals
+		WHERE
+			host_id = ? AND action = ?`
+
+	// use the primary, the request may have been created just before
+	var req fleet.HostLockWipeApprovalRequest
+	if err := sqlx.GetContext(ctx, ds.writer(ctx), &req, stmt, hostID, action); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostLockWipeApprovalRequest").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host lock/wipe approval request")
+	}
+	return &req, nil
+}
+
+func (ds *Datastore) ClaimHostLockWipeApprovalRequest(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error) {
+	// the creation time identifies the request, a request replaced in the
+	// meantime (e.g. after it expired) is not claimed.
+	const stmt = `DELETE FROM host_lock_wipe_approvals WHERE host_id = ? AND action = ? AND created_at = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, req.HostID, req.Action, req.CreatedAt)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "claim host lock/wipe approval request")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "claim host lock/wipe approval request rows affected")
+	}
+	return n == 1, nil
+}
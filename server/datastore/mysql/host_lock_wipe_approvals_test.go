@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLockWipeApprovals(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	u1 := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	u2 := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+	h1 := test.NewHost(t, ds, "h1.local", "", "1", "uuid-1", time.Now())
+	h2 := test.NewHost(t, ds, "h2.local", "", "2", "uuid-2", time.Now())
+
+	// no request yet
+	_, err := ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.NewHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock, u1.ID))
+	require.NoError(t, ds.NewHostLockWipeApprovalRequest(ctx, h2.ID, fleet.HostLockWipeActionWipe, u1.ID))
+
+	req, err := ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.NoError(t, err)
+	require.Equal(t, h1.ID, req.HostID)
+	require.Equal(t, fleet.HostLockWipeActionLock, req.Action)
+	require.NotNil(t, req.RequestedByUserID)
+	require.Equal(t, u1.ID, *req.RequestedByUserID)
+	require.False(t, req.IsExpired(time.Now()))
+
+	// requests are per action
+	_, err = ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionWipe)
+	require.True(t, fleet.IsNotFound(err))
+
+	// a new request replaces the existing one
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_lock_wipe_approvals SET created_at = ? WHERE host_id = ?`, time.Now().Add(-48*time.Hour), h1.ID)
+		return err
+	})
+	req, err = ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.NoError(t, err)
+	require.True(t, req.IsExpired(time.Now()))
+
+	require.NoError(t, ds.NewHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock, u2.ID))
+	req, err = ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.NoError(t, err)
+	require.Equal(t, u2.ID, *req.RequestedByUserID)
+	require.False(t, req.IsExpired(time.Now()))
+
+	// deleting the user keeps the request
+	require.NoError(t, ds.DeleteUser(ctx, u2.ID))
+	req, err = ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.NoError(t, err)
+	require.Nil(t, req.RequestedByUserID)
+
+	// a request replaced since it was read is not claimed
+	replaced := *req
+	replaced.CreatedAt = req.CreatedAt.Add(-time.Hour)
+	claimed, err := ds.ClaimHostLockWipeApprovalRequest(ctx, &replaced)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	claimed, err = ds.ClaimHostLockWipeApprovalRequest(ctx, req)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	_, err = ds.GetHostLockWipeApprovalRequest(ctx, h1.ID, fleet.HostLockWipeActionLock)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the request can only be claimed once
+	claimed, err = ds.ClaimHostLockWipeApprovalRequest(ctx, req)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	// the other request is untouched
+	_, err = ds.GetHostLockWipeApprovalRequest(ctx, h2.ID, fleet.HostLockWipeActionWipe)
+	require.NoError(t, err)
+}
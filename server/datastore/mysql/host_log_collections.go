@@ -0,0 +1,193 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var hostLogCollectionSelectColumns = fmt.Sprintf(`
+	id,
+	host_id,
+	execution_id,
+	type,
+	channels,
+	COALESCE(predicate, '') AS predicate,
+	start_time,
+	end_time,
+	user_id,
+	CASE
+		WHEN completed_at IS NULL THEN '%s'
+		WHEN COALESCE(error, '') <> '' THEN '%s'
+		ELSE '%s'
+	END AS status,
+	COALESCE(error, '') AS error,
+	size,
+	created_at,
+	completed_at
+`, fleet.HostLogCollectionStatusPending, fleet.HostLogCollectionStatusFailed, fleet.HostLogCollectionStatusCompleted)
+
+// hostLogCollectionRow is used to scan a host log collection along with its
+// JSON-encoded list of channels.
+type hostLogCollectionRow struct {
+	fleet.HostLogCollection
+	ChannelsJSON []byte `db:"channels"`
+}
+
+func (r *hostLogCollectionRow) toHostLogCollection() (*fleet.HostLogCollection, error) {
+	lc := r.HostLogCollection
+	if len(r.ChannelsJSON) > 0 {
+		if err := json.Unmarshal(r.ChannelsJSON, &lc.Channels); err != nil {
+			return nil, err
+		}
+	}
+	return &lc, nil
+}
+
+func (ds *Datastore) NewHostLogCollection(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error) {
+	const stmt = `
+  INSERT INTO host_log_collections (
+    host_id,
+    execution_id,
+    type,
+    channels,
+    predicate,
+    start_time,
+    end_time,
+    user_id
+  ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	var channels []byte
+	if len(payload.Channels) > 0 {
+		var err error
+		if channels, err = json.Marshal(payload.Channels); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "marshal log collection channels")
+		}
+	}
+
+	execID := uuid.New().String()
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		payload.HostID,
+		execID,
+		payload.Type,
+		channels,
+		payload.Predicate,
+		payload.StartTime,
+		payload.EndTime,
+		payload.UserID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new host log collection")
+	}
+	return ds.getHostLogCollection(ctx, ds.writer(ctx), execID)
+}
+
+func (ds *Datastore) GetHostLogCollection(ctx context.Context, execID string) (*fleet.HostLogCollection, error) {
+	return ds.getHostLogCollection(ctx, ds.reader(ctx), execID)
+}
+
+func (ds *Datastore) getHostLogCollection(ctx context.Context, q sqlx.QueryerContext, execID string) (*fleet.HostLogCollection, error) {
+	stmt := `SELECT ` + hostLogCollectionSelectColumns + ` FROM host_log_collections WHERE execution_id = ?`
+
+	var row hostLogCollectionRow
+	if err := sqlx.GetContext(ctx, q, &row, stmt, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostLogCollection").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host log collection")
+	}
+	lc, err := row.toHostLogCollection()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal log collection channels")
+	}
+	return lc, nil
+}
+
+func (ds *Datastore) GetHostLogCollectionData(ctx context.Context, execID string) ([]byte, error) {
+	var data []byte
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &data, `SELECT data FROM host_log_collections WHERE execution_id = ?`, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostLogCollection").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host log collection data")
+	}
+	return data, nil
+}
+
+func (ds *Datastore) ListHostLogCollections(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+	stmt := `SELECT ` + hostLogCollectionSelectColumns + ` FROM host_log_collections WHERE host_id = ? ORDER BY created_at DESC, id DESC`
+	return ds.listHostLogCollections(ctx, stmt, hostID)
+}
+
+func (ds *Datastore) ListPendingHostLogCollections(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+	stmt := `
+  SELECT ` + hostLogCollectionSelectColumns + `
+  FROM
+    host_log_collections
+  WHERE
+    host_id = ? AND
+    completed_at IS NULL AND
+    created_at >= DATE_SUB(NOW(), INTERVAL ? SECOND)
+  ORDER BY
+    created_at ASC, id ASC`
+
+	seconds := int(fleet.MaxHostLogCollectionPendingAge.Seconds())
+	return ds.listHostLogCollections(ctx, stmt, hostID, seconds)
+}
+
+func (ds *Datastore) listHostLogCollections(ctx context.Context, stmt string, args ...any) ([]*fleet.HostLogCollection, error) {
+	var rows []*hostLogCollectionRow
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host log collections")
+	}
+
+	results := make([]*fleet.HostLogCollection, 0, len(rows))
+	for _, row := range rows {
+		lc, err := row.toHostLogCollection()
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal log collection channels")
+		}
+		results = append(results, lc)
+	}
+	return results, nil
+}
+
+func (ds *Datastore) SetHostLogCollectionResult(ctx context.Context, result *fleet.HostLogCollectionResultPayload) (*fleet.HostLogCollection, error) {
+	const updStmt = `
+  UPDATE host_log_collections SET
+    error = ?,
+    data = ?,
+    size = ?,
+    completed_at = CURRENT_TIMESTAMP
+  WHERE
+    host_id = ? AND
+    execution_id = ? AND
+    completed_at IS NULL`
+
+	var data []byte
+	if result.Error == "" {
+		data = result.Data
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, updStmt,
+		result.Error,
+		data,
+		len(data),
+		result.HostID,
+		result.ExecutionID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host log collection result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// the log collection does not exist for that host or already has a
+		// result, ignore the new one.
+		return nil, nil
+	}
+	return ds.getHostLogCollection(ctx, ds.writer(ctx), result.ExecutionID)
+}
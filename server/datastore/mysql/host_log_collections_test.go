@@ -0,0 +1,175 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLogCollections(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"RequestAndResult", testHostLogCollectionsRequestAndResult},
+		{"ListPending", testHostLogCollectionsListPending},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostLogCollectionsRequestAndResult(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	start := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	end := start.Add(time.Hour)
+
+	winReq, err := ds.NewHostLogCollection(ctx, &fleet.HostLogCollectionPayload{
+		HostID:    1,
+		Type:      fleet.HostLogCollectionWindowsEventLog,
+		Channels:  []string{"System", "Security"},
+		StartTime: start,
+		EndTime:   end,
+		UserID:    &user.ID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, winReq.ExecutionID)
+	require.Equal(t, uint(1), winReq.HostID)
+	require.Equal(t, []string{"System", "Security"}, winReq.Channels)
+	require.Empty(t, winReq.Predicate)
+	require.Equal(t, start, winReq.StartTime)
+	require.Equal(t, end, winReq.EndTime)
+	require.Equal(t, user.ID, *winReq.UserID)
+	require.Equal(t, fleet.HostLogCollectionStatusPending, winReq.Status)
+	require.Nil(t, winReq.CompletedAt)
+
+	macReq, err := ds.NewHostLogCollection(ctx, &fleet.HostLogCollectionPayload{
+		HostID:    2,
+		Type:      fleet.HostLogCollectionMacOSUnifiedLog,
+		Predicate: `subsystem == "com.apple.securityd"`,
+		StartTime: start,
+		EndTime:   end,
+	})
+	require.NoError(t, err)
+	require.Nil(t, macReq.Channels)
+	require.Equal(t, `subsystem == "com.apple.securityd"`, macReq.Predicate)
+	require.Nil(t, macReq.UserID)
+
+	_, err = ds.GetHostLogCollection(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.GetHostLogCollectionData(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+
+	// a result from a different host is ignored
+	lc, err := ds.SetHostLogCollectionResult(ctx, &fleet.HostLogCollectionResultPayload{
+		HostID:      2,
+		ExecutionID: winReq.ExecutionID,
+		Data:        []byte("logs"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, lc)
+
+	lc, err = ds.SetHostLogCollectionResult(ctx, &fleet.HostLogCollectionResultPayload{
+		HostID:      1,
+		ExecutionID: winReq.ExecutionID,
+		Data:        []byte("logs"),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, lc)
+	require.Equal(t, fleet.HostLogCollectionStatusCompleted, lc.Status)
+	require.EqualValues(t, 4, lc.Size)
+	require.NotNil(t, lc.CompletedAt)
+
+	data, err := ds.GetHostLogCollectionData(ctx, winReq.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("logs"), data)
+
+	// a second result is ignored
+	lc, err = ds.SetHostLogCollectionResult(ctx, &fleet.HostLogCollectionResultPayload{
+		HostID:      1,
+		ExecutionID: winReq.ExecutionID,
+		Data:        []byte("other logs"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, lc)
+	data, err = ds.GetHostLogCollectionData(ctx, winReq.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("logs"), data)
+
+	// an error result does not store data
+	lc, err = ds.SetHostLogCollectionResult(ctx, &fleet.HostLogCollectionResultPayload{
+		HostID:      2,
+		ExecutionID: macReq.ExecutionID,
+		Error:       "log: permission denied",
+		Data:        []byte("partial"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLogCollectionStatusFailed, lc.Status)
+	require.Equal(t, "log: permission denied", lc.Error)
+	require.Zero(t, lc.Size)
+	data, err = ds.GetHostLogCollectionData(ctx, macReq.ExecutionID)
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	list, err := ds.ListHostLogCollections(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, winReq.ExecutionID, list[0].ExecutionID)
+	list, err = ds.ListHostLogCollections(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func testHostLogCollectionsListPending(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	start := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	newReq := func(hostID uint) *fleet.HostLogCollection {
+		lc, err := ds.NewHostLogCollection(ctx, &fleet.HostLogCollectionPayload{
+			HostID:    hostID,
+			Type:      fleet.HostLogCollectionMacOSUnifiedLog,
+			Predicate: "process == \"sshd\"",
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+		})
+		require.NoError(t, err)
+		return lc
+	}
+
+	pending, err := ds.ListPendingHostLogCollections(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	lc1 := newReq(1)
+	lc2 := newReq(1)
+	lc3 := newReq(1)
+	newReq(2)
+
+	// lc2 is too old to still be sent to the host
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_log_collections SET created_at = ? WHERE execution_id = ?`,
+			time.Now().Add(-fleet.MaxHostLogCollectionPendingAge-time.Hour), lc2.ExecutionID)
+		return err
+	})
+	// lc3 is completed
+	_, err = ds.SetHostLogCollectionResult(ctx, &fleet.HostLogCollectionResultPayload{HostID: 1, ExecutionID: lc3.ExecutionID, Data: []byte("x")})
+	require.NoError(t, err)
+
+	pending, err = ds.ListPendingHostLogCollections(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, lc1.ExecutionID, pending[0].ExecutionID)
+	require.Equal(t, fleet.HostLogCollectionStatusPending, pending[0].Status)
+}
@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostUUID, bypassCode string) error {
+	encrypted, err := encrypt([]byte(bypassCode), ds.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypting activation lock bypass code")
+	}
+
+	// a new code replaces the previous one, which is no longer valid.
+	const stmt = `
+	INSERT INTO host_mdm_apple_activation_lock_bypass_codes
+		(host_id, bypass_code)
+	SELECT id, ? FROM hosts WHERE uuid = ?
+	ON DUPLICATE KEY UPDATE
+		bypass_code = VALUES(bypass_code),
+		clear_command_uuid = NULL`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, encrypted, hostUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host activation lock bypass code")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error) {
+	const stmt = `
+	SELECT
+		host_id, bypass_code, clear_command_uuid, updated_at
+	FROM host_mdm_apple_activation_lock_bypass_codes
+	WHERE host_id = ?`
+
+	var row struct {
+		fleet.HostMDMAppleActivationLockBypassCode
+		Encrypted []byte `db:"bypass_code"`
+	}
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &row, stmt, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostMDMAppleActivationLockBypassCode").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host activation lock bypass code")
+	}
+
+	decrypted, err := decrypt(row.Encrypted, ds.serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decrypting activation lock bypass code")
+	}
+	code := row.HostMDMAppleActivationLockBypassCode
+	code.BypassCode = string(decrypted)
+	return &code, nil
+}
+
+func (ds *Datastore) SetHostMDMAppleActivationLockBypassCodePendingClear(ctx context.Context, hostID uint, clearCommandUUID string) error {
+	const stmt = `
+	UPDATE host_mdm_apple_activation_lock_bypass_codes
+	SET clear_command_uuid = ?
+	WHERE host_id = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, clearCommandUUID, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host activation lock bypass code pending clear")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostMDMAppleActivationLockBypassCode").WithID(hostID))
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx context.Context, hostUUID, cmdUUID string, succeeded bool) error {
+	stmt := `
+	UPDATE host_mdm_apple_activation_lock_bypass_codes bc
+		JOIN hosts h ON h.id = bc.host_id
+	SET bc.clear_command_uuid = NULL
+	WHERE h.uuid = ? AND bc.clear_command_uuid = ?`
+	if succeeded {
+		stmt = `
+	DELETE bc FROM host_mdm_apple_activation_lock_bypass_codes bc
+		JOIN hosts h ON h.id = bc.host_id
+	WHERE h.uuid = ? AND bc.clear_command_uuid = ?`
+	}
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostUUID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host activation lock bypass code from clear result")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMDMAppleActivationLockBypassCode(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	host := test.NewHost(t, ds, "iphone", "", "iphonekey", "iphone-uuid", time.Now(), test.WithPlatform("ios"))
+
+	_, err := ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.SetHostMDMAppleActivationLockBypassCodePendingClear(ctx, host.ID, "clear-1")
+	require.True(t, fleet.IsNotFound(err))
+
+	// the code of an unknown host is ignored
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCode(ctx, "unknown-uuid", "AAAA-BBBB"))
+
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCode(ctx, host.UUID, "AAAA-BBBB"))
+	code, err := ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "AAAA-BBBB", code.BypassCode)
+	require.Nil(t, code.ClearCommandUUID)
+
+	// the code is stored encrypted
+	var stored []byte
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &stored, `SELECT bypass_code FROM host_mdm_apple_activation_lock_bypass_codes WHERE host_id = ?`, host.ID)
+	})
+	require.NotContains(t, string(stored), "AAAA-BBBB")
+
+	// the clear command fails, the code is kept
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCodePendingClear(ctx, host.ID, "clear-1"))
+	code, err = ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, ptr.String("clear-1"), code.ClearCommandUUID)
+	require.NoError(t, ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx, host.UUID, "clear-1", false))
+	code, err = ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Nil(t, code.ClearCommandUUID)
+
+	// a new code replaces the previous one and the pending clear command
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCodePendingClear(ctx, host.ID, "clear-2"))
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCode(ctx, host.UUID, "CCCC-DDDD"))
+	code, err = ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "CCCC-DDDD", code.BypassCode)
+	require.Nil(t, code.ClearCommandUUID)
+
+	// the result of another command is ignored
+	require.NoError(t, ds.SetHostMDMAppleActivationLockBypassCodePendingClear(ctx, host.ID, "clear-3"))
+	require.NoError(t, ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx, host.UUID, "clear-2", true))
+	_, err = ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx, host.UUID, "clear-3", true))
+	_, err = ds.GetHostMDMAppleActivationLockBypassCode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
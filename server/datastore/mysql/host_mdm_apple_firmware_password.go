@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// firmwarePasswordRow is a row of host_mdm_apple_firmware_passwords with the
+// encrypted password.
+type firmwarePasswordRow struct {
+	fleet.HostMDMAppleFirmwarePassword
+	Encrypted []byte `db:"password"`
+}
+
+const selectFirmwarePasswordStmt = `
+	SELECT
+		fp.host_id, h.uuid AS host_uuid, fp.password, fp.verified, fp.set_command_uuid, fp.password_updated_at
+	FROM host_mdm_apple_firmware_passwords fp
+		JOIN hosts h ON h.id = fp.host_id`
+
+func (ds *Datastore) getFirmwarePassword(ctx context.Context, q sqlx.QueryerContext, where string, args ...any) (*fleet.HostMDMAppleFirmwarePassword, error) {
+	var row firmwarePasswordRow
+	if err := sqlx.GetContext(ctx, q, &row, selectFirmwarePasswordStmt+` WHERE `+where, args...); err != nil {
+		return nil, err
+	}
+
+	pwd := row.HostMDMAppleFirmwarePassword
+	if len(row.Encrypted) > 0 {
+		decrypted, err := decrypt(row.Encrypted, ds.serverPrivateKey)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "decrypting firmware password")
+		}
+		pwd.Password = string(decrypted)
+	}
+	return &pwd, nil
+}
+
+func (ds *Datastore) UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*fleet.HostMDMAppleFirmwarePassword, error) {
+	if errorMessage != "" {
+		// the host keeps its current password.
+		const stmt = `
+	UPDATE host_mdm_apple_firmware_passwords fp
+		JOIN hosts h ON h.id = fp.host_id
+	SET
+		fp.pending_password = NULL,
+		fp.set_command_uuid = NULL,
+		fp.error_message = ?
+	WHERE h.uuid = ? AND fp.set_command_uuid = ?`
+		if _, err := ds.writer(ctx).ExecContext(ctx, stmt, errorMessage, hostUUID, cmdUUID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "update host firmware password from failed set result")
+		}
+		return nil, nil
+	}
+
+	const stmt = `
+	UPDATE host_mdm_apple_firmware_passwords fp
+		JOIN hosts h ON h.id = fp.host_id
+	SET
+		fp.password = fp.pending_password,
+		fp.pending_password = NULL,
+		fp.set_command_uuid = NULL,
+		fp.error_message = NULL,
+		fp.verify_command_uuid = NULL,
+		fp.verified = NULL,
+		fp.password_updated_at = CURRENT_TIMESTAMP
+	WHERE h.uuid = ? AND fp.set_command_uuid = ?`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, hostUUID, cmdUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host firmware password from set result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// not the pending command of the host, or its result was already
+		// processed.
+		return nil, nil
+	}
+
+	pwd, err := ds.getFirmwarePassword(ctx, ds.writer(ctx), `h.uuid = ?`, hostUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get updated host firmware password")
+	}
+	return pwd, nil
+}
+
+func (ds *Datastore) SetHostMDMAppleFirmwarePasswordPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error {
+	const stmt = `
+	UPDATE host_mdm_apple_firmware_passwords
+	SET verify_command_uuid = ?
+	WHERE host_id = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, verifyCmdUUID, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host firmware password pending verify")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostMDMAppleFirmwarePassword").WithID(hostID))
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx context.Context, hostUUID, cmdUUID string, verified bool) error {
+	const stmt = `
+	UPDATE host_mdm_apple_firmware_passwords fp
+		JOIN hosts h ON h.id = fp.host_id
+	SET
+		fp.verify_command_uuid = NULL,
+		fp.verified = ?
+	WHERE h.uuid = ? AND fp.verify_command_uuid = ?`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, verified, hostUUID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host firmware password from verify result")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostMDMAppleFirmwarePassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error) {
+	pwd, err := ds.getFirmwarePassword(ctx, ds.reader(ctx), `fp.host_id = ?`, hostID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostMDMAppleFirmwarePassword").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host firmware password")
+	}
+	return pwd, nil
+}
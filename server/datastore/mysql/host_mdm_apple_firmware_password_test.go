@@ -0,0 +1,96 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMDMAppleFirmwarePassword(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	storage, err := ds.NewMDMAppleMDMStorage(nil, nil)
+	require.NoError(t, err)
+
+	mac := test.NewHost(t, ds, "mac", "", "mackey", "mac-uuid", time.Now(), test.WithPlatform("darwin"))
+	nanoEnroll(t, ds, mac, false)
+
+	enqueueSet := func(cmdUUID, password string) {
+		cmd := &mdm.Command{CommandUUID: cmdUUID, Raw: []byte("<?xml")}
+		cmd.Command.RequestType = "SetFirmwarePassword"
+		require.NoError(t, storage.EnqueueSetFirmwarePasswordCommand(ctx, mac, cmd, password))
+	}
+
+	_, err = ds.GetHostMDMAppleFirmwarePassword(ctx, mac.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the password is not available until the host acknowledges the command
+	enqueueSet("set-1", "first")
+	pwd, err := ds.GetHostMDMAppleFirmwarePassword(ctx, mac.ID)
+	require.NoError(t, err)
+	require.Empty(t, pwd.Password)
+	require.Equal(t, ptr.String("set-1"), pwd.SetCommandUUID)
+
+	// the pending password is stored encrypted
+	var stored []byte
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &stored, `SELECT pending_password FROM host_mdm_apple_firmware_passwords WHERE host_id = ?`, mac.ID)
+	})
+	require.NotEmpty(t, stored)
+	require.NotContains(t, string(stored), "first")
+
+	// the result of another command is ignored
+	pwd, err = ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, mac.UUID, "set-other", "")
+	require.NoError(t, err)
+	require.Nil(t, pwd)
+
+	pwd, err = ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, mac.UUID, "set-1", "")
+	require.NoError(t, err)
+	require.NotNil(t, pwd)
+	require.Equal(t, mac.ID, pwd.HostID)
+	require.Equal(t, mac.UUID, pwd.HostUUID)
+	require.Equal(t, "first", pwd.Password)
+	require.Nil(t, pwd.SetCommandUUID)
+	require.Nil(t, pwd.Verified)
+	require.NotNil(t, pwd.UpdatedAt)
+
+	// verification
+	require.NoError(t, ds.SetHostMDMAppleFirmwarePasswordPendingVerify(ctx, mac.ID, "verify-1"))
+	require.NoError(t, ds.UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx, mac.UUID, "verify-other", false))
+	require.NoError(t, ds.UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx, mac.UUID, "verify-1", true))
+	pwd, err = ds.GetHostMDMAppleFirmwarePassword(ctx, mac.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", pwd.Password)
+	require.Equal(t, ptr.Bool(true), pwd.Verified)
+	err = ds.SetHostMDMAppleFirmwarePasswordPendingVerify(ctx, mac.ID+100, "verify-x")
+	require.True(t, fleet.IsNotFound(err))
+
+	// the current password is kept if the new one is rejected
+	enqueueSet("set-2", "second")
+	pwd, err = ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, mac.UUID, "set-2", "The firmware password was not changed.")
+	require.NoError(t, err)
+	require.Nil(t, pwd)
+	pwd, err = ds.GetHostMDMAppleFirmwarePassword(ctx, mac.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", pwd.Password)
+	require.Equal(t, ptr.Bool(true), pwd.Verified)
+	require.Nil(t, pwd.SetCommandUUID)
+
+	// the new password replaces the current one once acknowledged
+	enqueueSet("set-3", "third")
+	pwd, err = ds.GetHostMDMAppleFirmwarePassword(ctx, mac.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", pwd.Password)
+	pwd, err = ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, mac.UUID, "set-3", "")
+	require.NoError(t, err)
+	require.Equal(t, "third", pwd.Password)
+	require.Nil(t, pwd.Verified)
+}
@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewHostLostMode(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings, enableCommandUUID string) error {
+	const stmt = `
+	INSERT INTO host_mdm_apple_lost_mode
+		(host_id, status, message, phone_number, footnote, enable_command_uuid)
+	VALUES
+		(?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		status = VALUES(status),
+		message = VALUES(message),
+		phone_number = VALUES(phone_number),
+		footnote = VALUES(footnote),
+		enable_command_uuid = VALUES(enable_command_uuid),
+		disable_command_uuid = NULL`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, fleet.HostLostModeStatusPendingEnable,
+		settings.Message, settings.PhoneNumber, settings.Footnote, enableCommandUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host lost mode")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostLostMode(ctx context.Context, hostID uint) (*fleet.HostLostMode, error) {
+	const stmt = `
+	SELECT
+		host_id, status, message, phone_number, footnote, enable_command_uuid, disable_command_uuid, updated_at
+	FROM host_mdm_apple_lost_mode
+	WHERE host_id = ?`
+
+	var lm fleet.HostLostMode
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &lm, stmt, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostLostMode").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host lost mode")
+	}
+	return &lm, nil
+}
+
+func (ds *Datastore) SetHostLostModePendingDisable(ctx context.Context, hostID uint, disableCommandUUID string) error {
+	const stmt = `
+	UPDATE host_mdm_apple_lost_mode
+	SET status = ?, disable_command_uuid = ?
+	WHERE host_id = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, fleet.HostLostModeStatusPendingDisable, disableCommandUUID, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host lost mode pending disable")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostLostMode").WithID(hostID))
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostLostModeFromAppleMDMResult(ctx context.Context, hostUUID, cmdUUID, requestType string, succeeded bool) error {
+	// a bit of MDM protocol leaking in the mysql layer, same as for the
+	// lock/wipe status.
+	var stmt string
+	var args []any
+	switch {
+	case requestType == "EnableLostMode" && succeeded:
+		stmt = `
+	UPDATE host_mdm_apple_lost_mode lm
+		JOIN hosts h ON h.id = lm.host_id
+	SET lm.status = ?
+	WHERE h.uuid = ? AND lm.enable_command_uuid = ? AND lm.status = ?`
+		args = []any{fleet.HostLostModeStatusEnabled, hostUUID, cmdUUID, fleet.HostLostModeStatusPendingEnable}
+
+	case requestType == "EnableLostMode":
+		// Lost Mode was not enabled
+		stmt = `
+	DELETE lm FROM host_mdm_apple_lost_mode lm
+		JOIN hosts h ON h.id = lm.host_id
+	WHERE h.uuid = ? AND lm.enable_command_uuid = ? AND lm.status = ?`
+		args = []any{hostUUID, cmdUUID, fleet.HostLostModeStatusPendingEnable}
+
+	case requestType == "DisableLostMode" && succeeded:
+		stmt = `
+	DELETE lm FROM host_mdm_apple_lost_mode lm
+		JOIN hosts h ON h.id = lm.host_id
+	WHERE h.uuid = ? AND lm.disable_command_uuid = ?`
+		args = []any{hostUUID, cmdUUID}
+
+	case requestType == "DisableLostMode":
+		// the host is still in Lost Mode
+		stmt = `
+	UPDATE host_mdm_apple_lost_mode lm
+		JOIN hosts h ON h.id = lm.host_id
+	SET lm.status = ?, lm.disable_command_uuid = NULL
+	WHERE h.uuid = ? AND lm.disable_command_uuid = ?`
+		args = []any{fleet.HostLostModeStatusEnabled, hostUUID, cmdUUID}
+
+	default:
+		return nil
+	}
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host lost mode from result")
+	}
+	return nil
+}
+
+func (ds *Datastore) NewHostLocation(ctx context.Context, loc *fleet.HostMDMAppleLocation) error {
+	// the same result reported again does not create a new location.
+	const stmt = `
+	INSERT INTO host_mdm_apple_locations
+		(host_id, command_uuid, latitude, longitude, altitude, horizontal_accuracy,
+		vertical_accuracy, speed, course, reported_at)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		latitude = VALUES(latitude),
+		longitude = VALUES(longitude),
+		altitude = VALUES(altitude),
+		horizontal_accuracy = VALUES(horizontal_accuracy),
+		vertical_accuracy = VALUES(vertical_accuracy),
+		speed = VALUES(speed),
+		course = VALUES(course),
+		reported_at = VALUES(reported_at)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		loc.HostID, loc.CommandUUID, loc.Latitude, loc.Longitude, loc.Altitude, loc.HorizontalAccuracy,
+		loc.VerticalAccuracy, loc.Speed, loc.Course, loc.ReportedAt,
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host location")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostLastLocation(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error) {
+	const stmt = `
+	SELECT
+		host_id, command_uuid, latitude, longitude, altitude, horizontal_accuracy,
+		vertical_accuracy, speed, course, reported_at, created_at
+	FROM host_mdm_apple_locations
+	WHERE host_id = ?
+	ORDER BY created_at DESC, id DESC
+	LIMIT 1`
+
+	var loc fleet.HostMDMAppleLocation
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &loc, stmt, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostLocation").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host last location")
+	}
+	return &loc, nil
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMDMAppleLostMode(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"EnableDisable", testHostLostModeEnableDisable},
+		{"Failures", testHostLostModeFailures},
+		{"Locations", testHostLocations},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostLostModeEnableDisable(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "iphone", "", "iphonekey", "iphone-uuid", time.Now(), test.WithPlatform("ios"))
+
+	_, err := ds.GetHostLostMode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.SetHostLostModePendingDisable(ctx, host.ID, "disable-1")
+	require.True(t, fleet.IsNotFound(err))
+
+	settings := fleet.HostLostModeSettings{Message: "lost", PhoneNumber: "555-0100", Footnote: "reward"}
+	require.NoError(t, ds.NewHostLostMode(ctx, host.ID, settings, "enable-1"))
+	lm, err := ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusPendingEnable, lm.Status)
+	require.Equal(t, settings, lm.HostLostModeSettings)
+	require.Equal(t, "enable-1", lm.EnableCommandUUID)
+	require.Nil(t, lm.DisableCommandUUID)
+
+	// the result of another command does not change the status
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "enable-0", "EnableLostMode", true))
+	lm, err = ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusPendingEnable, lm.Status)
+
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "enable-1", "EnableLostMode", true))
+	lm, err = ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusEnabled, lm.Status)
+
+	require.NoError(t, ds.SetHostLostModePendingDisable(ctx, host.ID, "disable-1"))
+	lm, err = ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusPendingDisable, lm.Status)
+	require.Equal(t, ptr.String("disable-1"), lm.DisableCommandUUID)
+
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "disable-1", "DisableLostMode", true))
+	_, err = ds.GetHostLostMode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testHostLostModeFailures(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "ipad", "", "ipadkey", "ipad-uuid", time.Now(), test.WithPlatform("ipados"))
+
+	// the enable command fails, the host is not in Lost Mode
+	require.NoError(t, ds.NewHostLostMode(ctx, host.ID, fleet.HostLostModeSettings{Message: "lost"}, "enable-1"))
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "enable-1", "EnableLostMode", false))
+	_, err := ds.GetHostLostMode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the disable command fails, the host is still in Lost Mode
+	require.NoError(t, ds.NewHostLostMode(ctx, host.ID, fleet.HostLostModeSettings{PhoneNumber: "555-0100"}, "enable-2"))
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "enable-2", "EnableLostMode", true))
+	require.NoError(t, ds.SetHostLostModePendingDisable(ctx, host.ID, "disable-1"))
+	require.NoError(t, ds.UpdateHostLostModeFromAppleMDMResult(ctx, host.UUID, "disable-1", "DisableLostMode", false))
+	lm, err := ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusEnabled, lm.Status)
+	require.Nil(t, lm.DisableCommandUUID)
+	require.Equal(t, "555-0100", lm.PhoneNumber)
+
+	// enabling again replaces the settings
+	require.NoError(t, ds.NewHostLostMode(ctx, host.ID, fleet.HostLostModeSettings{Message: "new"}, "enable-3"))
+	lm, err = ds.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusPendingEnable, lm.Status)
+	require.Equal(t, fleet.HostLostModeSettings{Message: "new"}, lm.HostLostModeSettings)
+	require.Equal(t, "enable-3", lm.EnableCommandUUID)
+}
+
+func testHostLocations(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	_, err := ds.GetHostLastLocation(ctx, 1)
+	require.True(t, fleet.IsNotFound(err))
+
+	reportedAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	require.NoError(t, ds.NewHostLocation(ctx, &fleet.HostMDMAppleLocation{
+		HostID:             1,
+		CommandUUID:        "location-1",
+		Latitude:           45.5,
+		Longitude:          -73.5,
+		HorizontalAccuracy: ptr.Float64(10),
+		ReportedAt:         &reportedAt,
+	}))
+	// the same result reported again does not create a new location
+	require.NoError(t, ds.NewHostLocation(ctx, &fleet.HostMDMAppleLocation{
+		HostID:             1,
+		CommandUUID:        "location-1",
+		Latitude:           45.5,
+		Longitude:          -73.5,
+		HorizontalAccuracy: ptr.Float64(10),
+		ReportedAt:         &reportedAt,
+	}))
+	var count int
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &count, `SELECT COUNT(*) FROM host_mdm_apple_locations WHERE host_id = 1`)
+	})
+	require.Equal(t, 1, count)
+
+	loc, err := ds.GetHostLastLocation(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 45.5, loc.Latitude)
+	require.Equal(t, -73.5, loc.Longitude)
+	require.Equal(t, 10.0, *loc.HorizontalAccuracy)
+	require.Nil(t, loc.Altitude)
+	require.Equal(t, reportedAt, *loc.ReportedAt)
+
+	// a newer location is returned
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_mdm_apple_locations SET created_at = ? WHERE host_id = 1`, time.Now().Add(-time.Hour))
+		return err
+	})
+	require.NoError(t, ds.NewHostLocation(ctx, &fleet.HostMDMAppleLocation{HostID: 1, CommandUUID: "location-2", Latitude: 48.8, Longitude: 2.3}))
+	require.NoError(t, ds.NewHostLocation(ctx, &fleet.HostMDMAppleLocation{HostID: 2, CommandUUID: "location-2", Latitude: 1, Longitude: 1}))
+	loc, err = ds.GetHostLastLocation(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 48.8, loc.Latitude)
+	require.Nil(t, loc.ReportedAt)
+}
@@ -0,0 +1,205 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// recoveryLockPasswordRow is a row of host_mdm_apple_recovery_lock_passwords
+// with the encrypted password.
+type recoveryLockPasswordRow struct {
+	fleet.HostMDMAppleRecoveryLockPassword
+	Encrypted []byte `db:"password"`
+}
+
+func (ds *Datastore) decryptRecoveryLockPassword(ctx context.Context, row recoveryLockPasswordRow) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	pwd := row.HostMDMAppleRecoveryLockPassword
+	if len(row.Encrypted) > 0 {
+		decrypted, err := decrypt(row.Encrypted, ds.serverPrivateKey)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "decrypting recovery lock password")
+		}
+		pwd.Password = string(decrypted)
+	}
+	return &pwd, nil
+}
+
+func (ds *Datastore) ListHostsForAppleRecoveryLockRotation(ctx context.Context, rotateBefore, retryBefore time.Time, limit int) ([]*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	// Recovery Lock is only supported by Apple silicon Macs, which osquery
+	// reports as arm64e.
+	const stmt = `
+SELECT
+	h.id AS host_id,
+	h.uuid AS host_uuid,
+	rl.password,
+	rl.verified,
+	rl.set_command_uuid,
+	rl.password_updated_at
+FROM
+	hosts h
+	JOIN nano_enrollments ne
+		ON ne.device_id = h.uuid
+	LEFT JOIN host_mdm_apple_recovery_lock_passwords rl
+		ON rl.host_id = h.id
+WHERE
+	h.platform = 'darwin' AND
+	h.cpu_type LIKE 'arm64%' AND
+	ne.enabled = 1 AND
+	ne.type = 'Device' AND
+	(
+		rl.host_id IS NULL OR (
+			rl.set_command_uuid IS NULL AND
+			COALESCE(rl.verified, 1) = 1 AND
+			(
+				(rl.error_message IS NOT NULL AND rl.set_at < ?) OR
+				(rl.error_message IS NULL AND (rl.password_updated_at IS NULL OR rl.password_updated_at < ?))
+			)
+		)
+	)
+ORDER BY rl.password_updated_at ASC, h.id ASC
+LIMIT ?`
+
+	var rows []recoveryLockPasswordRow
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, retryBefore, rotateBefore, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list hosts for recovery lock rotation")
+	}
+
+	pwds := make([]*fleet.HostMDMAppleRecoveryLockPassword, 0, len(rows))
+	for _, row := range rows {
+		pwd, err := ds.decryptRecoveryLockPassword(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		pwds = append(pwds, pwd)
+	}
+	return pwds, nil
+}
+
+func (ds *Datastore) SetHostMDMAppleRecoveryLockPending(ctx context.Context, hostID uint, newPassword, setCmdUUID string) error {
+	encrypted, err := encrypt([]byte(newPassword), ds.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypting recovery lock password")
+	}
+
+	const stmt = `
+	INSERT INTO host_mdm_apple_recovery_lock_passwords
+		(host_id, pending_password, set_command_uuid, set_at)
+	VALUES
+		(?, ?, ?, CURRENT_TIMESTAMP)
+	ON DUPLICATE KEY UPDATE
+		pending_password = VALUES(pending_password),
+		set_command_uuid = VALUES(set_command_uuid),
+		set_at = VALUES(set_at)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, encrypted, setCmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "set host recovery lock pending")
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostMDMAppleRecoveryLockFromSetResult(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	if errorMessage != "" {
+		// the host keeps its current password.
+		const stmt = `
+	UPDATE host_mdm_apple_recovery_lock_passwords rl
+		JOIN hosts h ON h.id = rl.host_id
+	SET
+		rl.pending_password = NULL,
+		rl.set_command_uuid = NULL,
+		rl.error_message = ?
+	WHERE h.uuid = ? AND rl.set_command_uuid = ?`
+		if _, err := ds.writer(ctx).ExecContext(ctx, stmt, errorMessage, hostUUID, cmdUUID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "update host recovery lock from failed set result")
+		}
+		return nil, nil
+	}
+
+	const stmt = `
+	UPDATE host_mdm_apple_recovery_lock_passwords rl
+		JOIN hosts h ON h.id = rl.host_id
+	SET
+		rl.password = rl.pending_password,
+		rl.pending_password = NULL,
+		rl.set_command_uuid = NULL,
+		rl.error_message = NULL,
+		rl.verify_command_uuid = NULL,
+		rl.verified = NULL,
+		rl.password_updated_at = CURRENT_TIMESTAMP
+	WHERE h.uuid = ? AND rl.set_command_uuid = ?`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, hostUUID, cmdUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host recovery lock from set result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// not a command sent by the rotation, or its result was already
+		// processed.
+		return nil, nil
+	}
+
+	const getStmt = `
+	SELECT
+		rl.host_id, h.uuid AS host_uuid, rl.password, rl.verified, rl.set_command_uuid, rl.password_updated_at
+	FROM host_mdm_apple_recovery_lock_passwords rl
+		JOIN hosts h ON h.id = rl.host_id
+	WHERE h.uuid = ?`
+	var row recoveryLockPasswordRow
+	if err := sqlx.GetContext(ctx, ds.writer(ctx), &row, getStmt, hostUUID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get updated host recovery lock password")
+	}
+	return ds.decryptRecoveryLockPassword(ctx, row)
+}
+
+func (ds *Datastore) SetHostMDMAppleRecoveryLockPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error {
+	const stmt = `
+	UPDATE host_mdm_apple_recovery_lock_passwords
+	SET verify_command_uuid = ?
+	WHERE host_id = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, verifyCmdUUID, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host recovery lock pending verify")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostMDMAppleRecoveryLockPassword").WithID(hostID))
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx context.Context, hostUUID, cmdUUID string, verified bool) error {
+	const stmt = `
+	UPDATE host_mdm_apple_recovery_lock_passwords rl
+		JOIN hosts h ON h.id = rl.host_id
+	SET
+		rl.verify_command_uuid = NULL,
+		rl.verified = ?
+	WHERE h.uuid = ? AND rl.verify_command_uuid = ?`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, verified, hostUUID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host recovery lock from verify result")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostMDMAppleRecoveryLockPassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	const stmt = `
+	SELECT
+		rl.host_id, h.uuid AS host_uuid, rl.password, rl.verified, rl.set_command_uuid, rl.password_updated_at
+	FROM host_mdm_apple_recovery_lock_passwords rl
+		JOIN hosts h ON h.id = rl.host_id
+	WHERE rl.host_id = ? AND rl.password IS NOT NULL`
+
+	var row recoveryLockPasswordRow
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &row, stmt, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostMDMAppleRecoveryLockPassword").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host recovery lock password")
+	}
+	return ds.decryptRecoveryLockPassword(ctx, row)
+}
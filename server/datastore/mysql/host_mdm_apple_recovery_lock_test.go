@@ -0,0 +1,124 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMDMAppleRecoveryLockPassword(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	newMac := func(name, cpuType string, enroll bool) *fleet.Host {
+		h := test.NewHost(t, ds, name, "", name+"key", name+"-uuid", time.Now(), test.WithPlatform("darwin"))
+		ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			_, err := q.ExecContext(ctx, `UPDATE hosts SET cpu_type = ? WHERE id = ?`, cpuType, h.ID)
+			return err
+		})
+		if enroll {
+			nanoEnroll(t, ds, h, false)
+		}
+		return h
+	}
+	mac1 := newMac("mac1", "arm64e", true)
+	mac2 := newMac("mac2", "arm64e", true)
+	newMac("intel", "x86_64h", true)
+	newMac("unenrolled", "arm64e", false)
+
+	listUUIDs := func(rotateBefore, retryBefore time.Time) []string {
+		pwds, err := ds.ListHostsForAppleRecoveryLockRotation(ctx, rotateBefore, retryBefore, 10)
+		require.NoError(t, err)
+		var uuids []string
+		for _, p := range pwds {
+			uuids = append(uuids, p.HostUUID)
+		}
+		return uuids
+	}
+	now := time.Now()
+
+	_, err := ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac1.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// only the enrolled Apple silicon Macs get a password
+	require.ElementsMatch(t, []string{mac1.UUID, mac2.UUID}, listUUIDs(now.Add(-time.Hour), now.Add(-time.Hour)))
+
+	require.NoError(t, ds.SetHostMDMAppleRecoveryLockPending(ctx, mac1.ID, "first", "set-1"))
+	require.NoError(t, ds.SetHostMDMAppleRecoveryLockPending(ctx, mac2.ID, "first-2", "set-2"))
+	// the hosts with a pending command are excluded
+	require.Empty(t, listUUIDs(now.Add(time.Hour), now.Add(time.Hour)))
+	// the password is not available until the host acknowledges the command
+	_, err = ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac1.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the result of another command is ignored
+	pwd, err := ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, mac1.UUID, "set-other", "")
+	require.NoError(t, err)
+	require.Nil(t, pwd)
+
+	pwd, err = ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, mac1.UUID, "set-1", "")
+	require.NoError(t, err)
+	require.NotNil(t, pwd)
+	require.Equal(t, mac1.ID, pwd.HostID)
+	require.Equal(t, "first", pwd.Password)
+	require.Nil(t, pwd.Verified)
+	require.NotNil(t, pwd.UpdatedAt)
+
+	// the password is stored encrypted
+	var stored []byte
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &stored, `SELECT password FROM host_mdm_apple_recovery_lock_passwords WHERE host_id = ?`, mac1.ID)
+	})
+	require.NotContains(t, string(stored), "first")
+
+	// the command fails on the second host, it is retried after retryBefore
+	pwd, err = ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, mac2.UUID, "set-2", "NotNow")
+	require.NoError(t, err)
+	require.Nil(t, pwd)
+	_, err = ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac2.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.Empty(t, listUUIDs(now.Add(-time.Hour), now.Add(-time.Hour)))
+	require.Equal(t, []string{mac2.UUID}, listUUIDs(now.Add(-time.Hour), now.Add(time.Hour)))
+
+	// verification
+	require.NoError(t, ds.SetHostMDMAppleRecoveryLockPendingVerify(ctx, mac1.ID, "verify-1"))
+	require.NoError(t, ds.UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx, mac1.UUID, "verify-1", true))
+	pwd, err = ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", pwd.Password)
+	require.Equal(t, ptr.Bool(true), pwd.Verified)
+	err = ds.SetHostMDMAppleRecoveryLockPendingVerify(ctx, mac2.ID+100, "verify-x")
+	require.True(t, fleet.IsNotFound(err))
+
+	// the password is rotated after rotateBefore, the current one is returned
+	pwds, err := ds.ListHostsForAppleRecoveryLockRotation(ctx, now.Add(time.Hour), now.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, pwds, 1)
+	require.Equal(t, mac1.ID, pwds[0].HostID)
+	require.Equal(t, "first", pwds[0].Password)
+
+	// the previous password is kept until the new one is acknowledged
+	require.NoError(t, ds.SetHostMDMAppleRecoveryLockPending(ctx, mac1.ID, "second", "set-3"))
+	pwd, err = ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", pwd.Password)
+	require.Equal(t, ptr.String("set-3"), pwd.SetCommandUUID)
+	pwd, err = ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, mac1.UUID, "set-3", "")
+	require.NoError(t, err)
+	require.Equal(t, "second", pwd.Password)
+	require.Nil(t, pwd.Verified)
+
+	// a host whose password failed the verification is not rotated
+	require.NoError(t, ds.SetHostMDMAppleRecoveryLockPendingVerify(ctx, mac1.ID, "verify-2"))
+	require.NoError(t, ds.UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx, mac1.UUID, "verify-2", false))
+	pwd, err = ds.GetHostMDMAppleRecoveryLockPassword(ctx, mac1.ID)
+	require.NoError(t, err)
+	require.Equal(t, ptr.Bool(false), pwd.Verified)
+	require.Empty(t, listUUIDs(now.Add(time.Hour), now.Add(-time.Hour)))
+}
@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetOrUpdateHostMDMAppleSecurityInfo(ctx context.Context, hostID uint, info *fleet.HostMDMAppleSecurityInfo) error {
+	// all the attributes are replaced, the ones not reported by the host in
+	// its latest response are reset to NULL.
+	const stmt = `
+	INSERT INTO host_mdm_apple_security_info
+		(host_id, fde_enabled, firewall_enabled, firewall_block_all_incoming, firewall_stealth_mode,
+		sip_enabled, secure_boot_level, remote_desktop_enabled)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		fde_enabled = VALUES(fde_enabled),
+		firewall_enabled = VALUES(firewall_enabled),
+		firewall_block_all_incoming = VALUES(firewall_block_all_incoming),
+		firewall_stealth_mode = VALUES(firewall_stealth_mode),
+		sip_enabled = VALUES(sip_enabled),
+		secure_boot_level = VALUES(secure_boot_level),
+		remote_desktop_enabled = VALUES(remote_desktop_enabled),
+		updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		hostID, info.FileVaultEnabled, info.FirewallEnabled, info.FirewallBlockAllIncoming, info.FirewallStealthMode,
+		info.SIPEnabled, info.SecureBootLevel, info.RemoteDesktopEnabled,
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host mdm apple security info")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostMDMAppleSecurityInfo(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+	const stmt = `
+	SELECT
+		fde_enabled, firewall_enabled, firewall_block_all_incoming, firewall_stealth_mode,
+		sip_enabled, secure_boot_level, remote_desktop_enabled, updated_at
+	FROM host_mdm_apple_security_info
+	WHERE host_id = ?`
+
+	var info fleet.HostMDMAppleSecurityInfo
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &info, stmt, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostMDMAppleSecurityInfo").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host mdm apple security info")
+	}
+	return &info, nil
+}
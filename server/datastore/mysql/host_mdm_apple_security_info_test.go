@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMDMAppleSecurityInfo(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	_, err := ds.GetHostMDMAppleSecurityInfo(ctx, 1)
+	require.True(t, fleet.IsNotFound(err))
+
+	err = ds.SetOrUpdateHostMDMAppleSecurityInfo(ctx, 1, &fleet.HostMDMAppleSecurityInfo{
+		FileVaultEnabled:         ptr.Bool(true),
+		FirewallEnabled:          ptr.Bool(true),
+		FirewallBlockAllIncoming: ptr.Bool(false),
+		FirewallStealthMode:      ptr.Bool(true),
+		SIPEnabled:               ptr.Bool(true),
+		SecureBootLevel:          ptr.String("full"),
+		RemoteDesktopEnabled:     ptr.Bool(false),
+	})
+	require.NoError(t, err)
+
+	info, err := ds.GetHostMDMAppleSecurityInfo(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, *info.FileVaultEnabled)
+	require.True(t, *info.FirewallEnabled)
+	require.False(t, *info.FirewallBlockAllIncoming)
+	require.True(t, *info.FirewallStealthMode)
+	require.True(t, *info.SIPEnabled)
+	require.Equal(t, "full", *info.SecureBootLevel)
+	require.False(t, *info.RemoteDesktopEnabled)
+	require.False(t, info.UpdatedAt.IsZero())
+
+	// the attributes not reported anymore are reset
+	err = ds.SetOrUpdateHostMDMAppleSecurityInfo(ctx, 1, &fleet.HostMDMAppleSecurityInfo{
+		FileVaultEnabled: ptr.Bool(false),
+		SIPEnabled:       ptr.Bool(true),
+	})
+	require.NoError(t, err)
+
+	info, err = ds.GetHostMDMAppleSecurityInfo(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, *info.FileVaultEnabled)
+	require.True(t, *info.SIPEnabled)
+	require.Nil(t, info.FirewallEnabled)
+	require.Nil(t, info.SecureBootLevel)
+	require.Nil(t, info.RemoteDesktopEnabled)
+
+	// other hosts are not affected
+	_, err = ds.GetHostMDMAppleSecurityInfo(ctx, 2)
+	require.True(t, fleet.IsNotFound(err))
+}
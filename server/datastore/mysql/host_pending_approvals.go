@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostNotPendingApprovalCondition is a SQL condition that is true if the host
+// aliased as "h" is not pending enrollment approval. It is used to prevent
+// delivering configuration profiles to hosts that were not approved yet.
+const hostNotPendingApprovalCondition = `NOT EXISTS (SELECT 1 FROM host_pending_approvals hpa WHERE hpa.host_id = h.id)`
+
+// hostPendingApprovalSelect selects whether the host aliased as "h" is
+// pending enrollment approval, in the pending_approval column.
+const hostPendingApprovalSelect = `EXISTS (SELECT 1 FROM host_pending_approvals hpa WHERE hpa.host_id = h.id) AS pending_approval`
+
+// insertHostPendingApprovalDB marks a newly enrolled host as pending approval
+// if the enrollment approval mode is enabled.
+func insertHostPendingApprovalDB(ctx context.Context, tx sqlx.ExtContext, appCfg *fleet.AppConfig, hostID uint, source string) error {
+	if !appCfg.EnrollmentApproval.Enabled {
+		return nil
+	}
+	const stmt = `INSERT IGNORE INTO host_pending_approvals (host_id, source) VALUES (?, ?)`
+	if _, err := tx.ExecContext(ctx, stmt, hostID, source); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host pending approval")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListHostsPendingApproval(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostPendingApproval, error) {
+	stmt := fmt.Sprintf(`
+	SELECT
+		h.id AS host_id,
+		h.hostname,
+		h.computer_name,
+		h.hardware_serial,
+		h.hardware_model,
+		h.platform,
+		h.team_id,
+		t.name AS team_name,
+		(SELECT he.email FROM host_emails he WHERE he.host_id = h.id ORDER BY he.id LIMIT 1) AS owner_email,
+		hpa.source,
+		hpa.created_at
+	FROM host_pending_approvals hpa
+	JOIN hosts h ON h.id = hpa.host_id
+	LEFT JOIN teams t ON t.id = h.team_id
+	WHERE %s
+	ORDER BY hpa.created_at, h.id`, ds.whereFilterHostsByTeams(filter, "h"))
+
+	hosts := []*fleet.HostPendingApproval{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing hosts pending approval")
+	}
+	return hosts, nil
+}
+
+func (ds *Datastore) ApproveHostEnrollment(ctx context.Context, hostID uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM host_pending_approvals WHERE host_id = ?`, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "approve host enrollment")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostPendingApproval").WithID(hostID))
+	}
+	return nil
+}
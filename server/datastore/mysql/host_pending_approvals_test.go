@@ -0,0 +1,169 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPendingApprovals(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"EnrollPendingApproval", testEnrollPendingApproval},
+		{"ListAndApprove", testHostsPendingApprovalListAndApprove},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func setEnrollmentApproval(t *testing.T, ds *Datastore, enabled bool) {
+	ctx := context.Background()
+	appCfg, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	appCfg.EnrollmentApproval.Enabled = enabled
+	require.NoError(t, ds.SaveAppConfig(ctx, appCfg))
+}
+
+func testEnrollPendingApproval(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	// approval mode disabled, hosts are not pending
+	hOrbit, err := ds.EnrollOrbit(ctx, false, fleet.OrbitHostInfo{HardwareUUID: uuid.NewString(), HardwareSerial: "serial0"}, uuid.NewString(), nil)
+	require.NoError(t, err)
+	h, err := ds.Host(ctx, hOrbit.ID)
+	require.NoError(t, err)
+	require.False(t, h.PendingApproval)
+
+	setEnrollmentApproval(t, ds, true)
+
+	// new orbit enrollment is pending
+	orbitKey := uuid.NewString()
+	hwUUID := uuid.NewString()
+	hOrbit, err = ds.EnrollOrbit(ctx, false, fleet.OrbitHostInfo{HardwareUUID: hwUUID, HardwareSerial: "serial1"}, orbitKey, nil)
+	require.NoError(t, err)
+	h, err = ds.Host(ctx, hOrbit.ID)
+	require.NoError(t, err)
+	require.True(t, h.PendingApproval)
+	h, err = ds.LoadHostByOrbitNodeKey(ctx, orbitKey)
+	require.NoError(t, err)
+	require.True(t, h.PendingApproval)
+
+	// osquery enrolling for the same host is not a new enrollment
+	nodeKey := uuid.NewString()
+	hOsquery, err := ds.EnrollHost(ctx, false, hwUUID, hwUUID, "serial1", nodeKey, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, hOrbit.ID, hOsquery.ID)
+	h, err = ds.LoadHostByNodeKey(ctx, nodeKey)
+	require.NoError(t, err)
+	require.True(t, h.PendingApproval)
+
+	// new osquery enrollment is pending
+	hwUUID2 := uuid.NewString()
+	nodeKey2 := uuid.NewString()
+	hOsquery, err = ds.EnrollHost(ctx, false, hwUUID2, hwUUID2, "serial2", nodeKey2, nil, 0)
+	require.NoError(t, err)
+	h, err = ds.LoadHostByNodeKey(ctx, nodeKey2)
+	require.NoError(t, err)
+	require.True(t, h.PendingApproval)
+
+	// new manual MDM enrollment is pending
+	err = ds.MDMAppleUpsertHost(ctx, &fleet.Host{HardwareSerial: "serial3", UUID: uuid.NewString(), HardwareModel: "MacBookPro16,1", Platform: "darwin"})
+	require.NoError(t, err)
+
+	hosts, err := ds.ListHostsPendingApproval(ctx, fleet.TeamFilter{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	require.Equal(t, hOrbit.ID, hosts[0].HostID)
+	require.Equal(t, fleet.HostEnrollmentSourceOrbit, hosts[0].Source)
+	require.Equal(t, hOsquery.ID, hosts[1].HostID)
+	require.Equal(t, fleet.HostEnrollmentSourceOsquery, hosts[1].Source)
+	require.Equal(t, "serial3", hosts[2].HardwareSerial)
+	require.Equal(t, fleet.HostEnrollmentSourceMDM, hosts[2].Source)
+
+	// approved hosts that re-enroll are not pending anymore
+	require.NoError(t, ds.ApproveHostEnrollment(ctx, hOrbit.ID))
+	_, err = ds.EnrollOrbit(ctx, false, fleet.OrbitHostInfo{HardwareUUID: hwUUID, HardwareSerial: "serial1"}, uuid.NewString(), nil)
+	require.NoError(t, err)
+	h, err = ds.Host(ctx, hOrbit.ID)
+	require.NoError(t, err)
+	require.False(t, h.PendingApproval)
+
+	// disabling the mode does not approve the pending hosts
+	setEnrollmentApproval(t, ds, false)
+	h, err = ds.LoadHostByNodeKey(ctx, nodeKey2)
+	require.NoError(t, err)
+	require.True(t, h.PendingApproval)
+}
+
+func testHostsPendingApprovalListAndApprove(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tm1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	tm2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	globalAdmin := fleet.TeamFilter{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}}
+	team1Admin := fleet.TeamFilter{User: &fleet.User{Teams: []fleet.UserTeam{{Team: *tm1, Role: fleet.RoleAdmin}}}}
+
+	hosts, err := ds.ListHostsPendingApproval(ctx, globalAdmin)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	setEnrollmentApproval(t, ds, true)
+
+	enroll := func(serial string, teamID *uint) *fleet.Host {
+		h, err := ds.EnrollOrbit(ctx, false, fleet.OrbitHostInfo{HardwareUUID: uuid.NewString(), HardwareSerial: serial, Hostname: serial + ".local"}, uuid.NewString(), teamID)
+		require.NoError(t, err)
+		return h
+	}
+	h1 := enroll("serial1", &tm1.ID)
+	h2 := enroll("serial2", &tm2.ID)
+	h3 := enroll("serial3", nil)
+
+	_, err = ds.writer(ctx).Exec(`INSERT INTO host_emails (host_id, email, source) VALUES (?, ?, ?)`, h1.ID, "anna@example.com", fleet.DeviceMappingGoogleChromeProfiles)
+	require.NoError(t, err)
+
+	hosts, err = ds.ListHostsPendingApproval(ctx, globalAdmin)
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	require.Equal(t, h1.ID, hosts[0].HostID)
+	require.Equal(t, "serial1", hosts[0].HardwareSerial)
+	require.Equal(t, "serial1.local", hosts[0].Hostname)
+	require.Equal(t, &tm1.ID, hosts[0].TeamID)
+	require.Equal(t, ptr.String("team1"), hosts[0].TeamName)
+	require.Equal(t, ptr.String("anna@example.com"), hosts[0].OwnerEmail)
+	require.NotZero(t, hosts[0].CreatedAt)
+	require.Nil(t, hosts[1].OwnerEmail)
+	require.Nil(t, hosts[2].TeamID)
+	require.Nil(t, hosts[2].TeamName)
+
+	// team users only see the hosts of their teams
+	hosts, err = ds.ListHostsPendingApproval(ctx, team1Admin)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Equal(t, h1.ID, hosts[0].HostID)
+
+	require.NoError(t, ds.ApproveHostEnrollment(ctx, h2.ID))
+	err = ds.ApproveHostEnrollment(ctx, h2.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	hosts, err = ds.ListHostsPendingApproval(ctx, globalAdmin)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	require.Equal(t, h1.ID, hosts[0].HostID)
+	require.Equal(t, h3.ID, hosts[1].HostID)
+}
@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var hostPprofCaptureSelectColumns = fmt.Sprintf(`
+	id,
+	host_id,
+	execution_id,
+	type,
+	duration_seconds,
+	user_id,
+	CASE
+		WHEN completed_at IS NULL THEN '%s'
+		WHEN COALESCE(error, '') <> '' THEN '%s'
+		ELSE '%s'
+	END AS status,
+	COALESCE(error, '') AS error,
+	size,
+	created_at,
+	completed_at
+`, fleet.HostPprofCaptureStatusPending, fleet.HostPprofCaptureStatusFailed, fleet.HostPprofCaptureStatusCompleted)
+
+func (ds *Datastore) NewHostPprofCapture(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error) {
+	const stmt = `
+  INSERT INTO host_pprof_captures (
+    host_id,
+    execution_id,
+    type,
+    duration_seconds,
+    user_id
+  ) VALUES (?, ?, ?, ?, ?)`
+
+	execID := uuid.New().String()
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		payload.HostID,
+		execID,
+		payload.Type,
+		payload.DurationSeconds,
+		payload.UserID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new host pprof capture")
+	}
+	return ds.getHostPprofCapture(ctx, ds.writer(ctx), execID)
+}
+
+func (ds *Datastore) GetHostPprofCapture(ctx context.Context, execID string) (*fleet.HostPprofCapture, error) {
+	return ds.getHostPprofCapture(ctx, ds.reader(ctx), execID)
+}
+
+func (ds *Datastore) getHostPprofCapture(ctx context.Context, q sqlx.QueryerContext, execID string) (*fleet.HostPprofCapture, error) {
+	stmt := `SELECT ` + hostPprofCaptureSelectColumns + ` FROM host_pprof_captures WHERE execution_id = ?`
+
+	var pc fleet.HostPprofCapture
+	if err := sqlx.GetContext(ctx, q, &pc, stmt, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostPprofCapture").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host pprof capture")
+	}
+	return &pc, nil
+}
+
+func (ds *Datastore) GetHostPprofCaptureData(ctx context.Context, execID string) ([]byte, error) {
+	var data []byte
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &data, `SELECT data FROM host_pprof_captures WHERE execution_id = ?`, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostPprofCapture").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host pprof capture data")
+	}
+	return data, nil
+}
+
+func (ds *Datastore) ListHostPprofCaptures(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+	stmt := `SELECT ` + hostPprofCaptureSelectColumns + ` FROM host_pprof_captures WHERE host_id = ? ORDER BY created_at DESC, id DESC`
+
+	var results []*fleet.HostPprofCapture
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host pprof captures")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) ListPendingHostPprofCaptures(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+	stmt := `
+  SELECT ` + hostPprofCaptureSelectColumns + `
+  FROM
+    host_pprof_captures
+  WHERE
+    host_id = ? AND
+    completed_at IS NULL AND
+    created_at >= DATE_SUB(NOW(), INTERVAL ? SECOND)
+  ORDER BY
+    created_at ASC, id ASC`
+
+	seconds := int(fleet.MaxHostPprofCapturePendingAge.Seconds())
+	var results []*fleet.HostPprofCapture
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID, seconds); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list pending host pprof captures")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) SetHostPprofCaptureResult(ctx context.Context, result *fleet.HostPprofCaptureResultPayload) (*fleet.HostPprofCapture, error) {
+	const updStmt = `
+  UPDATE host_pprof_captures SET
+    error = ?,
+    data = ?,
+    size = ?,
+    completed_at = CURRENT_TIMESTAMP
+  WHERE
+    host_id = ? AND
+    execution_id = ? AND
+    completed_at IS NULL`
+
+	var data []byte
+	if result.Error == "" {
+		data = result.Data
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, updStmt,
+		result.Error,
+		data,
+		len(data),
+		result.HostID,
+		result.ExecutionID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host pprof capture result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// the pprof capture does not exist for that host or already has a
+		// result, ignore the new one.
+		return nil, nil
+	}
+	return ds.getHostPprofCapture(ctx, ds.writer(ctx), result.ExecutionID)
+}
@@ -0,0 +1,162 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPprofCaptures(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"RequestAndResult", testHostPprofCapturesRequestAndResult},
+		{"ListPending", testHostPprofCapturesListPending},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostPprofCapturesRequestAndResult(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	req1, err := ds.NewHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{
+		HostID:          1,
+		Type:            fleet.HostPprofProfileCPU,
+		DurationSeconds: 30,
+		UserID:          &user.ID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, req1.ExecutionID)
+	require.Equal(t, uint(1), req1.HostID)
+	require.Equal(t, fleet.HostPprofProfileCPU, req1.Type)
+	require.Equal(t, uint(30), req1.DurationSeconds)
+	require.Equal(t, user.ID, *req1.UserID)
+	require.Equal(t, fleet.HostPprofCaptureStatusPending, req1.Status)
+	require.Nil(t, req1.CompletedAt)
+
+	req2, err := ds.NewHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{
+		HostID: 2,
+		Type:   fleet.HostPprofProfileHeap,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostPprofProfileHeap, req2.Type)
+	require.Zero(t, req2.DurationSeconds)
+	require.Nil(t, req2.UserID)
+
+	_, err = ds.GetHostPprofCapture(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.GetHostPprofCaptureData(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+
+	// a result from a different host is ignored
+	pc, err := ds.SetHostPprofCaptureResult(ctx, &fleet.HostPprofCaptureResultPayload{
+		HostID:      2,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("profile"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, pc)
+
+	pc, err = ds.SetHostPprofCaptureResult(ctx, &fleet.HostPprofCaptureResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("profile"),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, pc)
+	require.Equal(t, fleet.HostPprofCaptureStatusCompleted, pc.Status)
+	require.EqualValues(t, 7, pc.Size)
+	require.NotNil(t, pc.CompletedAt)
+
+	data, err := ds.GetHostPprofCaptureData(ctx, req1.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("profile"), data)
+
+	// a second result is ignored
+	pc, err = ds.SetHostPprofCaptureResult(ctx, &fleet.HostPprofCaptureResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+		Data:        []byte("other profile"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, pc)
+	data, err = ds.GetHostPprofCaptureData(ctx, req1.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("profile"), data)
+
+	// an error result does not store data
+	pc, err = ds.SetHostPprofCaptureResult(ctx, &fleet.HostPprofCaptureResultPayload{
+		HostID:      2,
+		ExecutionID: req2.ExecutionID,
+		Error:       "a cpu profile is already being captured",
+		Data:        []byte("partial"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostPprofCaptureStatusFailed, pc.Status)
+	require.Equal(t, "a cpu profile is already being captured", pc.Error)
+	require.Zero(t, pc.Size)
+	data, err = ds.GetHostPprofCaptureData(ctx, req2.ExecutionID)
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	list, err := ds.ListHostPprofCaptures(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, req1.ExecutionID, list[0].ExecutionID)
+	list, err = ds.ListHostPprofCaptures(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func testHostPprofCapturesListPending(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	newReq := func(hostID uint) *fleet.HostPprofCapture {
+		pc, err := ds.NewHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{
+			HostID: hostID,
+			Type:   fleet.HostPprofProfileGoroutine,
+		})
+		require.NoError(t, err)
+		return pc
+	}
+
+	pending, err := ds.ListPendingHostPprofCaptures(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	pc1 := newReq(1)
+	pc2 := newReq(1)
+	pc3 := newReq(1)
+	newReq(2)
+
+	// pc2 is too old to still be sent to the host
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_pprof_captures SET created_at = ? WHERE execution_id = ?`,
+			time.Now().Add(-fleet.MaxHostPprofCapturePendingAge-time.Hour), pc2.ExecutionID)
+		return err
+	})
+	// pc3 is completed
+	_, err = ds.SetHostPprofCaptureResult(ctx, &fleet.HostPprofCaptureResultPayload{HostID: 1, ExecutionID: pc3.ExecutionID, Data: []byte("x")})
+	require.NoError(t, err)
+
+	pending, err = ds.ListPendingHostPprofCaptures(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, pc1.ExecutionID, pending[0].ExecutionID)
+	require.Equal(t, fleet.HostPprofCaptureStatusPending, pending[0].Status)
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var hostRemoteQuerySelectColumns = fmt.Sprintf(`
+	id,
+	host_id,
+	execution_id,
+	query,
+	user_id,
+	CASE
+		WHEN completed_at IS NULL THEN '%s'
+		WHEN COALESCE(error, '') <> '' THEN '%s'
+		ELSE '%s'
+	END AS status,
+	COALESCE(error, '') AS error,
+	row_count,
+	created_at,
+	completed_at
+`, fleet.HostRemoteQueryStatusPending, fleet.HostRemoteQueryStatusFailed, fleet.HostRemoteQueryStatusCompleted)
+
+func (ds *Datastore) NewHostRemoteQuery(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error) {
+	const stmt = `
+  INSERT INTO host_remote_queries (
+    host_id,
+    execution_id,
+    query,
+    user_id
+  ) VALUES (?, ?, ?, ?)`
+
+	execID := uuid.New().String()
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt,
+		payload.HostID,
+		execID,
+		payload.Query,
+		payload.UserID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new host remote query")
+	}
+	return ds.getHostRemoteQuery(ctx, ds.writer(ctx), execID)
+}
+
+func (ds *Datastore) GetHostRemoteQuery(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error) {
+	return ds.getHostRemoteQuery(ctx, ds.reader(ctx), execID)
+}
+
+func (ds *Datastore) getHostRemoteQuery(ctx context.Context, q sqlx.QueryerContext, execID string) (*fleet.HostRemoteQuery, error) {
+	stmt := `SELECT ` + hostRemoteQuerySelectColumns + `, results FROM host_remote_queries WHERE execution_id = ?`
+
+	var rq struct {
+		fleet.HostRemoteQuery
+		Results *json.RawMessage `db:"results"`
+	}
+	if err := sqlx.GetContext(ctx, q, &rq, stmt, execID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("HostRemoteQuery").WithName(execID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host remote query")
+	}
+	if rq.Results != nil {
+		if err := json.Unmarshal(*rq.Results, &rq.HostRemoteQuery.Rows); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal host remote query results")
+		}
+	}
+	return &rq.HostRemoteQuery, nil
+}
+
+func (ds *Datastore) ListHostRemoteQueries(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+	stmt := `SELECT ` + hostRemoteQuerySelectColumns + ` FROM host_remote_queries WHERE host_id = ? ORDER BY created_at DESC, id DESC`
+
+	var results []*fleet.HostRemoteQuery
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host remote queries")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) ListPendingHostRemoteQueries(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+	stmt := `
+  SELECT ` + hostRemoteQuerySelectColumns + `
+  FROM
+    host_remote_queries
+  WHERE
+    host_id = ? AND
+    completed_at IS NULL AND
+    created_at >= DATE_SUB(NOW(), INTERVAL ? SECOND)
+  ORDER BY
+    created_at ASC, id ASC`
+
+	seconds := int(fleet.MaxHostRemoteQueryPendingAge.Seconds())
+	var results []*fleet.HostRemoteQuery
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, stmt, hostID, seconds); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list pending host remote queries")
+	}
+	return results, nil
+}
+
+func (ds *Datastore) SetHostRemoteQueryResult(ctx context.Context, result *fleet.HostRemoteQueryResultPayload) (*fleet.HostRemoteQuery, error) {
+	const updStmt = `
+  UPDATE host_remote_queries SET
+    error = ?,
+    results = ?,
+    row_count = ?,
+    completed_at = CURRENT_TIMESTAMP
+  WHERE
+    host_id = ? AND
+    execution_id = ? AND
+    completed_at IS NULL`
+
+	var (
+		results  *string
+		rowCount int
+	)
+	if result.Error == "" {
+		rows := result.Rows
+		if rows == nil {
+			rows = []map[string]string{}
+		}
+		b, err := json.Marshal(rows)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "marshal host remote query results")
+		}
+		s := string(b)
+		results, rowCount = &s, len(rows)
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, updStmt,
+		result.Error,
+		results,
+		rowCount,
+		result.HostID,
+		result.ExecutionID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host remote query result")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// the remote query does not exist for that host or already has a
+		// result, ignore the new one.
+		return nil, nil
+	}
+	return ds.getHostRemoteQuery(ctx, ds.writer(ctx), result.ExecutionID)
+}
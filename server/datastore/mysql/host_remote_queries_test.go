@@ -0,0 +1,155 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRemoteQueries(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"RequestAndResult", testHostRemoteQueriesRequestAndResult},
+		{"ListPending", testHostRemoteQueriesListPending},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostRemoteQueriesRequestAndResult(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	req1, err := ds.NewHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{
+		HostID: 1,
+		Query:  "SELECT pid, name FROM processes",
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, req1.ExecutionID)
+	require.Equal(t, uint(1), req1.HostID)
+	require.Equal(t, "SELECT pid, name FROM processes", req1.Query)
+	require.Equal(t, user.ID, *req1.UserID)
+	require.Equal(t, fleet.HostRemoteQueryStatusPending, req1.Status)
+	require.Nil(t, req1.CompletedAt)
+	require.Nil(t, req1.Rows)
+
+	req2, err := ds.NewHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{
+		HostID: 2,
+		Query:  "SELECT * FROM no_such_table",
+	})
+	require.NoError(t, err)
+	require.Nil(t, req2.UserID)
+
+	_, err = ds.GetHostRemoteQuery(ctx, "no-such-id")
+	require.True(t, fleet.IsNotFound(err))
+
+	// a result from a different host is ignored
+	rq, err := ds.SetHostRemoteQueryResult(ctx, &fleet.HostRemoteQueryResultPayload{
+		HostID:      2,
+		ExecutionID: req1.ExecutionID,
+		Rows:        []map[string]string{{"pid": "1", "name": "launchd"}},
+	})
+	require.NoError(t, err)
+	require.Nil(t, rq)
+
+	rows := []map[string]string{{"pid": "1", "name": "launchd"}, {"pid": "2", "name": "orbit"}}
+	rq, err = ds.SetHostRemoteQueryResult(ctx, &fleet.HostRemoteQueryResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+		Rows:        rows,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, rq)
+	require.Equal(t, fleet.HostRemoteQueryStatusCompleted, rq.Status)
+	require.Equal(t, 2, rq.RowCount)
+	require.Equal(t, rows, rq.Rows)
+	require.NotNil(t, rq.CompletedAt)
+
+	// a second result is ignored
+	rq, err = ds.SetHostRemoteQueryResult(ctx, &fleet.HostRemoteQueryResultPayload{
+		HostID:      1,
+		ExecutionID: req1.ExecutionID,
+	})
+	require.NoError(t, err)
+	require.Nil(t, rq)
+	rq, err = ds.GetHostRemoteQuery(ctx, req1.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, rows, rq.Rows)
+
+	// an error result does not store rows
+	rq, err = ds.SetHostRemoteQueryResult(ctx, &fleet.HostRemoteQueryResultPayload{
+		HostID:      2,
+		ExecutionID: req2.ExecutionID,
+		Error:       "no such table: no_such_table",
+		Rows:        []map[string]string{{"a": "b"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostRemoteQueryStatusFailed, rq.Status)
+	require.Equal(t, "no such table: no_such_table", rq.Error)
+	require.Zero(t, rq.RowCount)
+	require.Nil(t, rq.Rows)
+
+	// the list does not include the rows
+	list, err := ds.ListHostRemoteQueries(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, req1.ExecutionID, list[0].ExecutionID)
+	require.Equal(t, 2, list[0].RowCount)
+	require.Nil(t, list[0].Rows)
+	list, err = ds.ListHostRemoteQueries(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func testHostRemoteQueriesListPending(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	newReq := func(hostID uint) *fleet.HostRemoteQuery {
+		rq, err := ds.NewHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{
+			HostID: hostID,
+			Query:  "SELECT 1",
+		})
+		require.NoError(t, err)
+		return rq
+	}
+
+	pending, err := ds.ListPendingHostRemoteQueries(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	rq1 := newReq(1)
+	rq2 := newReq(1)
+	rq3 := newReq(1)
+	newReq(2)
+
+	// rq2 is too old to still be sent to the host
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_remote_queries SET created_at = ? WHERE execution_id = ?`,
+			time.Now().Add(-fleet.MaxHostRemoteQueryPendingAge-time.Hour), rq2.ExecutionID)
+		return err
+	})
+	// rq3 is completed
+	_, err = ds.SetHostRemoteQueryResult(ctx, &fleet.HostRemoteQueryResultPayload{HostID: 1, ExecutionID: rq3.ExecutionID})
+	require.NoError(t, err)
+
+	pending, err = ds.ListPendingHostRemoteQueries(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, rq1.ExecutionID, pending[0].ExecutionID)
+	require.Equal(t, fleet.HostRemoteQueryStatusPending, pending[0].Status)
+}
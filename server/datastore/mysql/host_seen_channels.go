@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostSeenChannelColumns maps each channel to its column in host_seen_times.
+var hostSeenChannelColumns = map[fleet.HostSeenChannel]string{
+	fleet.HostSeenChannelMDM:          "mdm_seen_time",
+	fleet.HostSeenChannelOrbit:        "orbit_seen_time",
+	fleet.HostSeenChannelFleetDesktop: "desktop_seen_time",
+}
+
+// hostChannelSeenTimesSelect selects the channels' seen times of the host
+// joined with host_seen_times aliased as "hst".
+const hostChannelSeenTimesSelect = `hst.mdm_seen_time, hst.orbit_seen_time, hst.desktop_seen_time`
+
+// hostOnlineUntilSQL is the SQL expression of the time up to which the host
+// aliased as "h" (joined with host_seen_times aliased as "hst") is
+// considered online. It combines the osquery seen time with the seen time of
+// every other channel, and must remain synchronized with fleet.Host.Status.
+var hostOnlineUntilSQL = func() string {
+	exprs := []string{
+		fmt.Sprintf("DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(h.distributed_interval, h.config_tls_refresh) + %d SECOND)", fleet.OnlineIntervalBuffer),
+	}
+	for _, c := range fleet.HostSeenChannels {
+		exprs = append(exprs, fmt.Sprintf("COALESCE(DATE_ADD(hst.%s, INTERVAL %d SECOND), h.created_at)",
+			hostSeenChannelColumns[c], int(c.OnlineInterval().Seconds())))
+	}
+	return "GREATEST(" + strings.Join(exprs, ", ") + ")"
+}()
+
+// hostLastSeenSQL is the SQL expression of the last time the host aliased as
+// "h" (joined with host_seen_times aliased as "hst") was seen by any
+// channel, including osquery.
+var hostLastSeenSQL = func() string {
+	exprs := []string{"COALESCE(hst.seen_time, h.created_at)"}
+	for _, c := range fleet.HostSeenChannels {
+		exprs = append(exprs, fmt.Sprintf("COALESCE(hst.%s, h.created_at)", hostSeenChannelColumns[c]))
+	}
+	return "GREATEST(" + strings.Join(exprs, ", ") + ")"
+}()
+
+// MarkHostsSeenByChannel records that the hosts were seen at time t by the
+// channel.
+func (ds *Datastore) MarkHostsSeenByChannel(ctx context.Context, channel fleet.HostSeenChannel, hostIDs []uint, t time.Time) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+	col, ok := hostSeenChannelColumns[channel]
+	if !ok {
+		return ctxerr.Errorf(ctx, "unsupported host seen channel: %s", channel)
+	}
+
+	// Sort by host id to prevent deadlocks, as done in MarkHostsSeen.
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+
+	if err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var insertArgs []interface{}
+		for _, hostID := range hostIDs {
+			insertArgs = append(insertArgs, hostID, t)
+		}
+		insertValues := strings.TrimSuffix(strings.Repeat("(?, ?),", len(hostIDs)), ",")
+		query := fmt.Sprintf(`
+			INSERT INTO host_seen_times (host_id, %[1]s) VALUES %[2]s
+			ON DUPLICATE KEY UPDATE %[1]s = VALUES(%[1]s)`,
+			col, insertValues,
+		)
+		if _, err := tx.ExecContext(ctx, query, insertArgs...); err != nil {
+			return ctxerr.Wrap(ctx, err, "exec update")
+		}
+		return nil
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "MarkHostsSeenByChannel transaction")
+	}
+	return nil
+}
+
+// MarkHostMDMSeen records that the host identified by its UUID checked in
+// with the MDM server at time t.
+func (ds *Datastore) MarkHostMDMSeen(ctx context.Context, hostUUID string, t time.Time) error {
+	const stmt = `
+		INSERT INTO host_seen_times (host_id, mdm_seen_time)
+		SELECT id, ? FROM hosts WHERE uuid = ?
+		ON DUPLICATE KEY UPDATE mdm_seen_time = VALUES(mdm_seen_time)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, t, hostUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "mark host MDM seen")
+	}
+	return nil
+}
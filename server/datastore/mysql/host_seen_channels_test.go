@@ -0,0 +1,149 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostSeenChannels(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"MarkSeen", testHostSeenChannelsMarkSeen},
+		{"Status", testHostSeenChannelsStatus},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func newHostSeenChannelsHost(t *testing.T, ds *Datastore, name string, seenTime time.Time) *fleet.Host {
+	h, err := ds.NewHost(context.Background(), &fleet.Host{
+		Hostname:            name,
+		OsqueryHostID:       ptr.String(name),
+		NodeKey:             ptr.String(name),
+		UUID:                name + "-uuid",
+		Platform:            "darwin",
+		DistributedInterval: 10,
+		ConfigTLSRefresh:    10,
+		DetailUpdatedAt:     seenTime,
+		LabelUpdatedAt:      seenTime,
+		PolicyUpdatedAt:     seenTime,
+		SeenTime:            seenTime,
+	})
+	require.NoError(t, err)
+	return h
+}
+
+func testHostSeenChannelsMarkSeen(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	h1 := newHostSeenChannelsHost(t, ds, "h1", now)
+	h2 := newHostSeenChannelsHost(t, ds, "h2", now)
+
+	err := ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannel("nope"), []uint{h1.ID}, now)
+	require.Error(t, err)
+
+	require.NoError(t, ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannelOrbit, nil, now))
+	require.NoError(t, ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannelOrbit, []uint{h2.ID, h1.ID}, now))
+	require.NoError(t, ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannelFleetDesktop, []uint{h1.ID}, now.Add(-time.Minute)))
+	require.NoError(t, ds.MarkHostMDMSeen(ctx, h2.UUID, now.Add(-time.Hour)))
+	// unknown host UUIDs are ignored
+	require.NoError(t, ds.MarkHostMDMSeen(ctx, "no-such-uuid", now))
+
+	got, err := ds.Host(ctx, h1.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.HostChannelSeenTimes.MDM)
+	require.NotNil(t, got.HostChannelSeenTimes.Orbit)
+	require.Equal(t, now, got.HostChannelSeenTimes.Orbit.UTC())
+	require.NotNil(t, got.HostChannelSeenTimes.FleetDesktop)
+	require.Equal(t, now.Add(-time.Minute), got.HostChannelSeenTimes.FleetDesktop.UTC())
+	// the osquery seen time is not affected
+	require.Equal(t, now, got.SeenTime.UTC())
+
+	got, err = ds.Host(ctx, h2.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.HostChannelSeenTimes.MDM)
+	require.Equal(t, now.Add(-time.Hour), got.HostChannelSeenTimes.MDM.UTC())
+	require.Nil(t, got.HostChannelSeenTimes.FleetDesktop)
+
+	// marking the host seen by osquery does not affect the channels
+	require.NoError(t, ds.MarkHostsSeen(ctx, []uint{h2.ID}, now.Add(time.Minute)))
+	got, err = ds.Host(ctx, h2.ID)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), got.SeenTime.UTC())
+	require.NotNil(t, got.HostChannelSeenTimes.MDM)
+	require.NotNil(t, got.HostChannelSeenTimes.Orbit)
+}
+
+func testHostSeenChannelsStatus(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	longAgo := now.Add(-40 * 24 * time.Hour)
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+
+	// all hosts were last seen by osquery a long time ago
+	hosts := make([]*fleet.Host, 5)
+	for i := range hosts {
+		hosts[i] = newHostSeenChannelsHost(t, ds, fmt.Sprintf("h%d", i), longAgo)
+	}
+	// created a long time ago too, so they are not new and missing unless
+	// seen by a channel.
+	for _, h := range hosts {
+		_, err := ds.writer(ctx).ExecContext(ctx, `UPDATE hosts SET created_at = ? WHERE id = ?`, longAgo, h.ID)
+		require.NoError(t, err)
+	}
+
+	// hosts[0]: never seen by any channel, offline and missing
+	// hosts[1]: seen recently by MDM, online
+	require.NoError(t, ds.MarkHostMDMSeen(ctx, hosts[1].UUID, now.Add(-10*time.Minute)))
+	// hosts[2]: seen recently by orbit, online
+	require.NoError(t, ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannelOrbit, []uint{hosts[2].ID}, now.Add(-10*time.Second)))
+	// hosts[3]: seen by Fleet Desktop a while ago, offline but not missing
+	require.NoError(t, ds.MarkHostsSeenByChannel(ctx, fleet.HostSeenChannelFleetDesktop, []uint{hosts[3].ID}, now.Add(-time.Hour)))
+	// hosts[4]: seen recently by osquery only, online
+	require.NoError(t, ds.MarkHostsSeen(ctx, []uint{hosts[4].ID}, now))
+
+	for i, want := range []fleet.HostStatus{fleet.StatusOffline, fleet.StatusOnline, fleet.StatusOnline, fleet.StatusOffline, fleet.StatusOnline} {
+		h, err := ds.Host(ctx, hosts[i].ID)
+		require.NoError(t, err)
+		require.Equal(t, want, h.Status(now), "host %d", i)
+	}
+
+	online := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: fleet.StatusOnline}, 3)
+	for _, h := range online {
+		require.Equal(t, fleet.StatusOnline, h.Status(now))
+	}
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: fleet.StatusOffline}, 2)
+	missing := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: fleet.StatusMissing}, 1)
+	require.Equal(t, hosts[0].ID, missing[0].ID)
+
+	summary, err := ds.GenerateHostStatusStatistics(ctx, filter, now, nil, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, summary.TotalsHostsCount)
+	require.EqualValues(t, 3, summary.OnlineCount)
+	require.EqualValues(t, 2, summary.OfflineCount)
+	require.EqualValues(t, 1, summary.Missing30DaysCount)
+
+	metrics, err := ds.CountHostsInTargets(ctx, filter, fleet.HostTargets{HostIDs: []uint{hosts[0].ID, hosts[1].ID, hosts[3].ID}}, now)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, metrics.TotalHosts)
+	require.EqualValues(t, 1, metrics.OnlineHosts)
+	require.EqualValues(t, 2, metrics.OfflineHosts)
+	require.EqualValues(t, 1, metrics.MissingInActionHosts)
+}
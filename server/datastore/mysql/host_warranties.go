@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostWarrantySelect selects the purchase and warranty end dates of the host
+// aliased to h, to be loaded in the PurchaseDate and WarrantyEndDate fields of
+// fleet.Host.
+const hostWarrantySelect = `
+  (SELECT purchase_date FROM host_warranties WHERE host_id = h.id) AS purchase_date,
+  (SELECT warranty_end_date FROM host_warranties WHERE host_id = h.id) AS warranty_end_date`
+
+func (ds *Datastore) ListHostsForWarrantyLookup(ctx context.Context, vendors []fleet.WarrantyVendor, checkedBefore time.Time, limit int) ([]*fleet.Host, error) {
+	if len(vendors) == 0 {
+		return nil, nil
+	}
+
+	// the vendors match the prefix of the hardware vendor reported by the
+	// hosts, see fleet.WarrantyVendorForHardwareVendor.
+	vendorConds := make([]string, 0, len(vendors))
+	args := make([]interface{}, 0, len(vendors)+2)
+	for _, v := range vendors {
+		vendorConds = append(vendorConds, `LOWER(h.hardware_vendor) LIKE ?`)
+		args = append(args, string(v)+"%")
+	}
+	args = append(args, checkedBefore, limit)
+
+	// hosts never looked up come first, then the least recently looked up.
+	stmt := fmt.Sprintf(`
+	SELECT
+		h.id,
+		h.hardware_vendor,
+		h.hardware_serial
+	FROM
+		hosts h
+		LEFT OUTER JOIN host_warranties hw ON hw.host_id = h.id
+	WHERE
+		h.hardware_serial != '' AND
+		(%s) AND
+		(hw.host_id IS NULL OR hw.checked_at < ?)
+	ORDER BY
+		hw.checked_at IS NOT NULL, hw.checked_at, h.id
+	LIMIT ?`, strings.Join(vendorConds, " OR "))
+
+	var hosts []*fleet.Host
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list hosts for warranty lookup")
+	}
+	return hosts, nil
+}
+
+func (ds *Datastore) UpsertHostWarranties(ctx context.Context, warranties []*fleet.HostWarranty) error {
+	if len(warranties) == 0 {
+		return nil
+	}
+
+	const stmt = `
+	INSERT INTO host_warranties
+		(host_id, vendor, purchase_date, warranty_end_date, checked_at)
+	VALUES
+		%s
+	ON DUPLICATE KEY UPDATE
+		vendor = VALUES(vendor),
+		purchase_date = VALUES(purchase_date),
+		warranty_end_date = VALUES(warranty_end_date),
+		checked_at = VALUES(checked_at)`
+
+	const batchSize = 1000
+	for i := 0; i < len(warranties); i += batchSize {
+		end := i + batchSize
+		if end > len(warranties) {
+			end = len(warranties)
+		}
+		batch := warranties[i:end]
+
+		args := make([]interface{}, 0, len(batch)*4)
+		for _, w := range batch {
+			args = append(args, w.HostID, w.Vendor, w.PurchaseDate, w.WarrantyEndDate)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, CURRENT_TIMESTAMP),", len(batch)), ",")
+		if _, err := ds.writer(ctx).ExecContext(ctx, fmt.Sprintf(stmt, values), args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert host warranties")
+		}
+	}
+	return nil
+}
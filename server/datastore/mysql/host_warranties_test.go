@@ -0,0 +1,155 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostWarranties(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"LookupAndUpsert", testHostWarrantiesLookupAndUpsert},
+		{"ListHosts", testHostWarrantiesListHosts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func newWarrantyTestHost(t *testing.T, ds *Datastore, name, vendor, serial string) *fleet.Host {
+	h := test.NewHost(t, ds, name, "", name, name, time.Now())
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(context.Background(), `UPDATE hosts SET hardware_vendor = ?, hardware_serial = ? WHERE id = ?`, vendor, serial, h.ID)
+		return err
+	})
+	return h
+}
+
+func testHostWarrantiesLookupAndUpsert(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	mac := newWarrantyTestHost(t, ds, "mac", "Apple Inc.", "C02ABC")
+	dell := newWarrantyTestHost(t, ds, "dell", "Dell Inc.", "DELL1")
+	lenovo := newWarrantyTestHost(t, ds, "lenovo", "LENOVO", "LEN1")
+	newWarrantyTestHost(t, ds, "noserial", "Dell Inc.", "")
+	newWarrantyTestHost(t, ds, "vm", "VMware, Inc.", "VM1")
+
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	all := []fleet.WarrantyVendor{fleet.WarrantyVendorApple, fleet.WarrantyVendorDell, fleet.WarrantyVendorLenovo}
+	hosts, err := ds.ListHostsForWarrantyLookup(ctx, nil, time.Now(), 10)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	hosts, err = ds.ListHostsForWarrantyLookup(ctx, all, time.Now(), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{mac.ID, dell.ID, lenovo.ID}, hostIDs(hosts))
+	require.Equal(t, "Dell Inc.", hosts[1].HardwareVendor)
+	require.Equal(t, "DELL1", hosts[1].HardwareSerial)
+
+	hosts, err = ds.ListHostsForWarrantyLookup(ctx, []fleet.WarrantyVendor{fleet.WarrantyVendorDell}, time.Now(), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{dell.ID}, hostIDs(hosts))
+
+	hosts, err = ds.ListHostsForWarrantyLookup(ctx, all, time.Now(), 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint{mac.ID, dell.ID}, hostIDs(hosts))
+
+	purchased := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	ends := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, ds.UpsertHostWarranties(ctx, nil))
+	err = ds.UpsertHostWarranties(ctx, []*fleet.HostWarranty{
+		{HostID: mac.ID, Vendor: fleet.WarrantyVendorApple, PurchaseDate: &purchased, WarrantyEndDate: &ends},
+		{HostID: dell.ID, Vendor: fleet.WarrantyVendorDell},
+	})
+	require.NoError(t, err)
+
+	// the hosts just looked up are not returned, unless they were checked
+	// before the provided time.
+	hosts, err = ds.ListHostsForWarrantyLookup(ctx, all, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{lenovo.ID}, hostIDs(hosts))
+	hosts, err = ds.ListHostsForWarrantyLookup(ctx, all, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{lenovo.ID, mac.ID, dell.ID}, hostIDs(hosts))
+
+	host, err := ds.Host(ctx, mac.ID)
+	require.NoError(t, err)
+	require.NotNil(t, host.PurchaseDate)
+	require.True(t, purchased.Equal(*host.PurchaseDate))
+	require.NotNil(t, host.WarrantyEndDate)
+	require.True(t, ends.Equal(*host.WarrantyEndDate))
+
+	host, err = ds.Host(ctx, dell.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.PurchaseDate)
+	require.Nil(t, host.WarrantyEndDate)
+
+	// update the warranty
+	err = ds.UpsertHostWarranties(ctx, []*fleet.HostWarranty{
+		{HostID: dell.ID, Vendor: fleet.WarrantyVendorDell, WarrantyEndDate: &ends},
+	})
+	require.NoError(t, err)
+	host, err = ds.Host(ctx, dell.ID)
+	require.NoError(t, err)
+	require.Nil(t, host.PurchaseDate)
+	require.NotNil(t, host.WarrantyEndDate)
+
+	// deleting the host deletes its warranty
+	require.NoError(t, ds.DeleteHost(ctx, dell.ID))
+	var count int
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &count, `SELECT COUNT(*) FROM host_warranties`))
+	require.Equal(t, 1, count)
+}
+
+func testHostWarrantiesListHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	mac := newWarrantyTestHost(t, ds, "mac", "Apple Inc.", "C02ABC")
+	ends := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	err := ds.UpsertHostWarranties(ctx, []*fleet.HostWarranty{
+		{HostID: mac.ID, Vendor: fleet.WarrantyVendorApple, WarrantyEndDate: &ends},
+	})
+	require.NoError(t, err)
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hosts, err := ds.ListHosts(ctx, filter, fleet.HostListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Nil(t, hosts[0].WarrantyEndDate)
+
+	hosts, err = ds.ListHosts(ctx, filter, fleet.HostListOptions{PopulateWarranty: true})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Nil(t, hosts[0].PurchaseDate)
+	require.NotNil(t, hosts[0].WarrantyEndDate)
+	require.True(t, ends.Equal(*hosts[0].WarrantyEndDate))
+
+	label, err := ds.NewLabel(ctx, &fleet.Label{Name: "l1", Query: "select 1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, mac, map[uint]*bool{label.ID: ptr.Bool(true)}, time.Now(), false))
+	hosts, err = ds.ListHostsInLabel(ctx, filter, label.ID, fleet.HostListOptions{PopulateWarranty: true})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.NotNil(t, hosts[0].WarrantyEndDate)
+}
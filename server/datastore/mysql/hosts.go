@@ -3,10 +3,12 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -32,8 +34,9 @@ var (
 
 // Fixme: We should not make implementation details of the database schema part of the API.
 var defaultHostColumnTableAliases = map[string]string{
-	"created_at": "h.created_at",
-	"updated_at": "h.updated_at",
+	"created_at":   "h.created_at",
+	"updated_at":   "h.updated_at",
+	"health_score": "hhs.score",
 }
 
 func defaultHostColumnTableAlias(s string) string {
@@ -500,16 +503,37 @@ var hostRefs = []string{
 	"host_updates",
 	"host_disk_encryption_keys",
 	"host_software_installed_paths",
+	"host_software_inventory_sources",
 	"host_script_results",
 	"query_results",
 	"host_activities",
 	"host_mdm_actions",
 	"host_calendar_events",
+	"host_group_members",
+	"script_schedule_host_runs",
+	"host_pending_approvals",
+	"host_certificates",
+	"host_lock_wipe_approvals",
+	"host_notes",
+	"host_custom_field_values",
+	"host_warranties",
+	"host_health_scores",
+	"host_cloud_instances",
+	"host_mdm_apple_security_info",
+	"host_mdm_apple_lost_mode",
+	"host_mdm_apple_locations",
+	"host_mdm_apple_activation_lock_bypass_codes",
+	"host_mdm_apple_recovery_lock_passwords",
+	"host_mdm_apple_firmware_passwords",
+	"host_linux_disk_encryption_enforcement",
+	"host_cve_detections",
 }
 
 // NOTE: The following tables are explicity excluded from hostRefs list and accordingly are not
 // deleted from when a host is deleted in Fleet:
 // - host_dep_assignments
+// - host_enrollment_certificates, so that a revoked client certificate cannot
+// be used to enroll again after the host is deleted
 // - mdm tables (nano and windows) containing enrollment information, as we
 // want to keep the enrollment relationship even if the host is temporarily
 // deleted from the UI. Re-enrollment sometimes is not straightforward like it
@@ -520,21 +544,15 @@ var hostRefs = []string{
 // the host.uuid is not always named the same, so the map key is the table name
 // and the map value is the column name to match to the host.uuid.
 var additionalHostRefsByUUID = map[string]string{
-	"host_mdm_apple_profiles":           "host_uuid",
-	"host_mdm_apple_bootstrap_packages": "host_uuid",
-	"host_mdm_windows_profiles":         "host_uuid",
-	"host_mdm_apple_declarations":       "host_uuid",
+	"host_mdm_apple_profiles":             "host_uuid",
+	"host_mdm_apple_bootstrap_packages":   "host_uuid",
+	"host_mdm_windows_profiles":           "host_uuid",
+	"host_mdm_apple_declarations":         "host_uuid",
+	"host_mdm_apple_profile_certificates": "host_uuid",
+	"host_mdm_apple_fleetd_installs":      "host_uuid",
 }
 
 func (ds *Datastore) DeleteHost(ctx context.Context, hid uint) error {
-	delHostRef := func(tx sqlx.ExtContext, table string) error {
-		_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE host_id=?`, table), hid)
-		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "deleting %s for host %d", table, hid)
-		}
-		return nil
-	}
-
 	// load just the host uuid for the MDM tables that rely on this to be cleared.
 	var hostUUID string
 	if err := ds.writer(ctx).GetContext(ctx, &hostUUID, `SELECT uuid FROM hosts WHERE id = ?`, hid); err != nil {
@@ -542,34 +560,82 @@ func (ds *Datastore) DeleteHost(ctx context.Context, hid uint) error {
 	}
 
 	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
-		_, err := tx.ExecContext(ctx, `DELETE FROM hosts WHERE id = ?`, hid)
+		return deleteHostDB(ctx, tx, hid, hostUUID)
+	})
+}
+
+// deleteHostDB deletes the host and the rows referencing it. The uuid-based
+// tables are cleared only if hostUUID is not empty.
+func deleteHostDB(ctx context.Context, tx sqlx.ExtContext, hid uint, hostUUID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM hosts WHERE id = ?`, hid)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "delete host")
+	}
+
+	for _, table := range hostRefs {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE host_id=?`, table), hid)
 		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "delete host")
+			return ctxerr.Wrapf(ctx, err, "deleting %s for host %d", table, hid)
 		}
+	}
 
-		for _, table := range hostRefs {
-			err := delHostRef(tx, table)
-			if err != nil {
-				return err
+	_, err = tx.ExecContext(ctx, `DELETE FROM pack_targets WHERE type = ? AND target_id = ?`, fleet.TargetHost, hid)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "deleting pack_targets for host %d", hid)
+	}
+
+	// no point trying the uuid-based tables if the host's uuid is missing
+	if hostUUID != "" {
+		for table, col := range additionalHostRefsByUUID {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s` WHERE `%s`=?", table, col), hostUUID); err != nil {
+				return ctxerr.Wrapf(ctx, err, "deleting %s for host uuid %s", table, hostUUID)
 			}
 		}
+	}
 
-		_, err = tx.ExecContext(ctx, `DELETE FROM pack_targets WHERE type = ? AND target_id = ?`, fleet.TargetHost, hid)
-		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "deleting pack_targets for host %d", hid)
-		}
+	return nil
+}
 
-		// no point trying the uuid-based tables if the host's uuid is missing
-		if hostUUID != "" {
-			for table, col := range additionalHostRefsByUUID {
-				if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s` WHERE `%s`=?", table, col), hostUUID); err != nil {
-					return ctxerr.Wrapf(ctx, err, "deleting %s for host uuid %s", table, hostUUID)
-				}
+// orphanedHostRowsBatchSize is the maximum number of deleted hosts whose rows
+// are deleted at once from a table referencing hosts.
+const orphanedHostRowsBatchSize = 500
+
+func (ds *Datastore) CleanupOrphanedHostRows(ctx context.Context) (int64, error) {
+	var deleted int64
+	for _, table := range hostRefs {
+		for {
+			// using the writer to not consider the hosts just enrolled as deleted
+			// due to the replication lag.
+			var hostIDs []uint
+			stmt := fmt.Sprintf(`
+				SELECT DISTINCT t.host_id FROM %s t
+				LEFT JOIN hosts h ON h.id = t.host_id
+				WHERE h.id IS NULL AND t.host_id IS NOT NULL
+				LIMIT ?`, table)
+			if err := sqlx.SelectContext(ctx, ds.writer(ctx), &hostIDs, stmt, orphanedHostRowsBatchSize); err != nil {
+				return deleted, ctxerr.Wrapf(ctx, err, "selecting orphaned rows of %s", table)
+			}
+			if len(hostIDs) == 0 {
+				break
 			}
-		}
 
-		return nil
-	})
+			stmt, args, err := sqlx.In(fmt.Sprintf(`DELETE FROM %s WHERE host_id IN (?)`, table), hostIDs)
+			if err != nil {
+				return deleted, ctxerr.Wrapf(ctx, err, "building delete of orphaned rows of %s", table)
+			}
+			res, err := ds.writer(ctx).ExecContext(ctx, stmt, args...)
+			if err != nil {
+				return deleted, ctxerr.Wrapf(ctx, err, "deleting orphaned rows of %s", table)
+			}
+			n, _ := res.RowsAffected()
+			deleted += n
+
+			if len(hostIDs) < orphanedHostRowsBatchSize {
+				break
+			}
+		}
+	}
+	return deleted, nil
 }
 
 func (ds *Datastore) Host(ctx context.Context, id uint) (*fleet.Host, error) {
@@ -620,6 +686,7 @@ SELECT
   COALESCE(hd.gigs_total_disk_space, 0) as gigs_total_disk_space,
   hd.encrypted as disk_encryption_enabled,
   COALESCE(hst.seen_time, h.created_at) AS seen_time,
+  ` + hostChannelSeenTimesSelect + `,
   t.name AS team_name,
   COALESCE(hu.software_updated_at, h.created_at) AS software_updated_at,
   (CASE WHEN uptime = 0 THEN DATE('0001-01-01') ELSE DATE_SUB(h.detail_updated_at, INTERVAL uptime/1000 MICROSECOND) END) as last_restarted_at,
@@ -635,7 +702,13 @@ SELECT
   COALESCE(failing_policies.count, 0) AS total_issues_count,
   hoi.version AS orbit_version,
   hoi.desktop_version AS fleet_desktop_version,
-  hoi.scripts_enabled AS scripts_enabled
+  hoi.scripts_enabled AS scripts_enabled,
+  hoi.timezone AS timezone,
+  ` + hostCustomFieldsSelect + `,
+  ` + hostWarrantySelect + `,
+  ` + hostPendingApprovalSelect + `,
+  (SELECT score FROM host_health_scores WHERE host_id = h.id) AS health_score,
+  ` + hostCloudInstanceSelect + `
   ` + hostMDMSelect + `
 FROM
   hosts h
@@ -644,6 +717,7 @@ FROM
   LEFT JOIN host_updates hu ON (h.id = hu.host_id)
   LEFT JOIN host_disks hd ON hd.host_id = h.id
   LEFT JOIN host_orbit_info hoi ON hoi.host_id = h.id
+  ` + hostCloudInstanceJoin + `
   ` + hostMDMJoin + `
   JOIN (
     SELECT
@@ -905,9 +979,12 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
     COALESCE(hd.percent_disk_space_available, 0) as percent_disk_space_available,
     COALESCE(hd.gigs_total_disk_space, 0) as gigs_total_disk_space,
     COALESCE(hst.seen_time, h.created_at) AS seen_time,
+    ` + hostChannelSeenTimesSelect + `,
     t.name AS team_name,
     COALESCE(hu.software_updated_at, h.created_at) AS software_updated_at,
-	(CASE WHEN uptime = 0 THEN DATE('0001-01-01') ELSE DATE_SUB(h.detail_updated_at, INTERVAL uptime/1000 MICROSECOND) END) as last_restarted_at
+	(CASE WHEN uptime = 0 THEN DATE('0001-01-01') ELSE DATE_SUB(h.detail_updated_at, INTERVAL uptime/1000 MICROSECOND) END) as last_restarted_at,
+	hhs.score AS health_score,
+	` + hostCloudInstanceSelect + `
 	`
 
 	sql += hostMDMSelect
@@ -918,6 +995,14 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 		`
 	}
 
+	if opt.PopulateCustomFields {
+		sql += `,` + hostCustomFieldsSelect
+	}
+
+	if opt.PopulateWarranty {
+		sql += `,` + hostWarrantySelect
+	}
+
 	// See definition of HostFailingPoliciesCountOptimPageSizeThreshold for more details.
 	useHostPaginationOptim := opt.PerPage != 0 && opt.PerPage <= uint(HostFailingPoliciesCountOptimPageSizeThreshold)
 
@@ -973,6 +1058,75 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 	return hosts, nil
 }
 
+func (ds *Datastore) StreamHosts(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions, batchSize int, fn func([]*fleet.Host) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+
+	var lastID uint
+	for {
+		// IncludeMetadata must be disabled, as it loads an extra row to
+		// detect the next page.
+		opt.ListOptions = fleet.ListOptions{
+			OrderKey:   "h.id",
+			PerPage:    uint(batchSize),
+			MatchQuery: opt.ListOptions.MatchQuery,
+		}
+		if lastID > 0 {
+			opt.ListOptions.After = strconv.FormatUint(uint64(lastID), 10)
+		}
+
+		hosts, err := ds.ListHosts(ctx, filter, opt)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "stream hosts")
+		}
+		if len(hosts) == 0 {
+			return nil
+		}
+		if err := fn(hosts); err != nil {
+			return err
+		}
+		if len(hosts) < batchSize {
+			return nil
+		}
+		lastID = hosts[len(hosts)-1].ID
+	}
+}
+
+func (ds *Datastore) ListHostChanges(ctx context.Context, filter fleet.TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*fleet.HostChange, error) {
+	// updated_at has a precision of one second, so the hosts updated during
+	// the current second are excluded, as another host with a lower ID could
+	// still be updated during that second.
+	stmt := fmt.Sprintf(`
+		SELECT
+			h.id,
+			h.hostname,
+			COALESCE(hdn.display_name, '') AS display_name,
+			h.platform,
+			h.os_version,
+			h.osquery_version,
+			h.team_id,
+			t.name AS team_name,
+			COALESCE(hst.seen_time, h.created_at) AS seen_time,
+			h.updated_at
+		FROM hosts h
+		LEFT JOIN host_display_names hdn ON hdn.host_id = h.id
+		LEFT JOIN host_seen_times hst ON hst.host_id = h.id
+		LEFT JOIN teams t ON t.id = h.team_id
+		WHERE
+			(h.updated_at > ? OR (h.updated_at = ? AND h.id > ?)) AND
+			h.updated_at < NOW() AND
+			%s
+		ORDER BY h.updated_at, h.id
+		LIMIT ?`, ds.whereFilterHostsByTeams(filter, "h"))
+
+	var hosts []*fleet.HostChange
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt, afterUpdatedAt, afterUpdatedAt, afterID, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host changes")
+	}
+	return hosts, nil
+}
+
 // TODO(Sarah): Do we need to reconcile mutually exclusive filters?
 func (ds *Datastore) applyHostFilters(
 	ctx context.Context, opt fleet.HostListOptions, sqlStmt string, filter fleet.TeamFilter, params []interface{},
@@ -1063,11 +1217,15 @@ func (ds *Datastore) applyHostFilters(
     %s
     %s
     %s
+    %s
+    %s
     %s
 		WHERE TRUE AND %s AND %s AND %s AND %s
     `,
 
 		// JOINs
+		hostHealthScoreJoin,
+		hostCloudInstanceJoin,
 		hostMDMJoin,
 		deviceMappingJoin,
 		policyMembershipJoin,
@@ -1086,6 +1244,10 @@ func (ds *Datastore) applyHostFilters(
 	now := ds.clock.Now()
 	sqlStmt, params = filterHostsByStatus(now, sqlStmt, opt, params)
 	sqlStmt, params = filterHostsByTeam(sqlStmt, opt, params)
+	sqlStmt, params = filterHostsByHostGroup(sqlStmt, opt, params)
+	sqlStmt, params = filterHostsByCustomField(sqlStmt, opt, params)
+	sqlStmt, params = filterHostsByHealthScore(sqlStmt, opt, params)
+	sqlStmt, params = filterHostsByCloudInstance(sqlStmt, opt, params)
 	sqlStmt, params = filterHostsByPolicy(sqlStmt, opt, params)
 	sqlStmt, params = filterHostsByMDM(sqlStmt, opt, params)
 	var err error
@@ -1117,6 +1279,7 @@ func (ds *Datastore) applyHostFilters(
 	sqlStmt, params = filterHostsByOS(sqlStmt, opt, params)
 	sqlStmt, params = filterHostsByVulnerability(sqlStmt, opt, params)
 	sqlStmt, params, _ = hostSearchLike(sqlStmt, params, opt.MatchQuery, append(hostSearchColumns, "display_name")...)
+	sqlStmt, params = hostSearchCustomFields(sqlStmt, params, opt.MatchQuery)
 	sqlStmt, params = appendListOptionsWithCursorToSQL(sqlStmt, params, &opt.ListOptions)
 
 	return sqlStmt, params, nil
@@ -1140,6 +1303,17 @@ func filterHostsByTeam(sql string, opt fleet.HostListOptions, params []interface
 	return sql, params
 }
 
+func filterHostsByHostGroup(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.HostGroupIDFilter == nil {
+		return sql, params
+	}
+
+	sql += ` AND EXISTS (SELECT 1 FROM host_group_members hgm WHERE hgm.host_id = h.id AND hgm.host_group_id = ?)`
+	params = append(params, *opt.HostGroupIDFilter)
+
+	return sql, params
+}
+
 func filterHostsByMDM(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
 	if opt.MDMIDFilter != nil {
 		sql += ` AND hmdm.mdm_id = ?`
@@ -1201,13 +1375,13 @@ func filterHostsByStatus(now time.Time, sql string, opt fleet.HostListOptions, p
 		sql += "AND DATE_ADD(h.created_at, INTERVAL 1 DAY) >= ?"
 		params = append(params, now)
 	case fleet.StatusOnline:
-		sql += fmt.Sprintf("AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(h.distributed_interval, h.config_tls_refresh) + %d SECOND) > ?", fleet.OnlineIntervalBuffer)
+		sql += "AND " + hostOnlineUntilSQL + " > ?"
 		params = append(params, now)
 	case fleet.StatusOffline:
-		sql += fmt.Sprintf("AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(h.distributed_interval, h.config_tls_refresh) + %d SECOND) <= ?", fleet.OnlineIntervalBuffer)
+		sql += "AND " + hostOnlineUntilSQL + " <= ?"
 		params = append(params, now)
 	case fleet.StatusMIA, fleet.StatusMissing:
-		sql += "AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ?"
+		sql += "AND DATE_ADD(" + hostLastSeenSQL + ", INTERVAL 30 DAY) <= ?"
 		params = append(params, now)
 	}
 	return sql, params
@@ -1274,7 +1448,7 @@ func (ds *Datastore) filterHostsByOSSettingsStatus(sql string, opt fleet.HostLis
 	// or are servers. Similar logic could be applied to macOS hosts but is not included in this
 	// current implementation.
 
-	sqlFmt := ` AND h.platform IN('windows', 'darwin')`
+	sqlFmt := ` AND h.platform IN('windows', 'darwin', 'ios', 'ipados')`
 	if opt.TeamFilter == nil {
 		// OS settings filter is not compatible with the "all teams" option so append the "no team"
 		// filter here (note that filterHostsByTeam applies the "no team" filter if TeamFilter == 0)
@@ -1283,7 +1457,7 @@ func (ds *Datastore) filterHostsByOSSettingsStatus(sql string, opt fleet.HostLis
 	var whereMacOS, whereWindows string
 	sqlFmt += `
 AND ((h.platform = 'windows' AND (%s))
-OR (h.platform = 'darwin' AND (%s)))`
+OR (h.platform IN ('darwin', 'ios', 'ipados') AND (%s)))`
 
 	whereMacOS, paramsMacOS, err := subqueryOSSettingsStatusMac()
 	if err != nil {
@@ -1610,18 +1784,18 @@ func (ds *Datastore) GenerateHostStatusStatistics(ctx context.Context, filter fl
 	sqlStatement := fmt.Sprintf(`
 			SELECT
 				COUNT(*) total,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) missing_30_days_count,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) <= ? THEN 1 ELSE 0 END), 0) offline,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) > ? THEN 1 ELSE 0 END), 0) online,
+				COALESCE(SUM(CASE WHEN DATE_ADD(%[1]s, INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
+				COALESCE(SUM(CASE WHEN DATE_ADD(%[1]s, INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) missing_30_days_count,
+				COALESCE(SUM(CASE WHEN %[2]s <= ? THEN 1 ELSE 0 END), 0) offline,
+				COALESCE(SUM(CASE WHEN %[2]s > ? THEN 1 ELSE 0 END), 0) online,
 				COALESCE(SUM(CASE WHEN DATE_ADD(h.created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new,
-				%s
+				%[3]s
 			FROM hosts h
 			LEFT JOIN host_seen_times hst ON (h.id = hst.host_id)
-			%s
-			WHERE %s
+			%[4]s
+			WHERE %[5]s
 			LIMIT 1;
-		`, fleet.OnlineIntervalBuffer, fleet.OnlineIntervalBuffer, lowDiskSelect, hostDisksJoin, whereClause)
+		`, hostLastSeenSQL, hostOnlineUntilSQL, lowDiskSelect, hostDisksJoin, whereClause)
 
 	stmt, args, err := sqlx.In(sqlStatement, args...)
 	if err != nil {
@@ -1836,6 +2010,14 @@ func (ds *Datastore) EnrollOrbit(ctx context.Context, isMDMEnabled bool, hostInf
 			}
 			host.ID = uint(hostID)
 
+			appCfg, err := appConfigDB(ctx, tx)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "orbit enroll error getting app config")
+			}
+			if err := insertHostPendingApprovalDB(ctx, tx, appCfg, host.ID, fleet.HostEnrollmentSourceOrbit); err != nil {
+				return err
+			}
+
 		default:
 			return ctxerr.Wrap(ctx, err, "orbit enroll error selecting host details")
 		}
@@ -1895,6 +2077,14 @@ func (ds *Datastore) EnrollHost(ctx context.Context, isMDMEnabled bool, osqueryH
 			}
 			matchedID = uint(hostID)
 
+			appCfg, err := appConfigDB(ctx, tx)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "get app config")
+			}
+			if err := insertHostPendingApprovalDB(ctx, tx, appCfg, matchedID, fleet.HostEnrollmentSourceOsquery); err != nil {
+				return err
+			}
+
 		default:
 			// Prevent hosts from enrolling too often with the same identifier.
 			// Prior to adding this we saw many hosts (probably VMs) with the
@@ -2068,7 +2258,8 @@ func (ds *Datastore) LoadHostByNodeKey(ctx context.Context, nodeKey string) (*fl
       h.orbit_node_key,
       COALESCE(hd.gigs_disk_space_available, 0) as gigs_disk_space_available,
       COALESCE(hd.gigs_total_disk_space, 0) as gigs_total_disk_space,
-      COALESCE(hd.percent_disk_space_available, 0) as percent_disk_space_available
+      COALESCE(hd.percent_disk_space_available, 0) as percent_disk_space_available,
+      ` + hostPendingApprovalSelect + `
     FROM
       hosts h
     LEFT OUTER JOIN
@@ -2156,7 +2347,9 @@ func (ds *Datastore) LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string)
       IF(hdep.host_id AND ISNULL(hdep.deleted_at), true, false) AS dep_assigned_to_fleet,
       hd.encrypted as disk_encryption_enabled,
       t.name as team_name,
-      hdep.assign_profile_response AS dep_profile_assign_status
+      hdep.assign_profile_response AS dep_profile_assign_status,
+      hoi.timezone AS timezone,
+      ` + hostPendingApprovalSelect + `
     FROM
       hosts h
     LEFT OUTER JOIN
@@ -2183,6 +2376,10 @@ func (ds *Datastore) LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string)
       teams t
     ON
       h.team_id = t.id
+    LEFT OUTER JOIN
+      host_orbit_info hoi
+    ON
+      hoi.host_id = h.id
     WHERE
       h.orbit_node_key = ?`
 
@@ -2202,10 +2399,11 @@ func (ds *Datastore) LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string)
 				Name:                   *hostWithMDM.Name,
 				DEPProfileAssignStatus: hostWithMDM.DEPProfileAssignStatus,
 			}
-
-			host.MDM = fleet.MDMHostData{
-				EncryptionKeyAvailable: *hostWithMDM.EncryptionKeyAvailable,
-			}
+		}
+		// Linux hosts escrow their disk encryption key without being MDM
+		// enrolled, so the key availability is always loaded.
+		host.MDM = fleet.MDMHostData{
+			EncryptionKeyAvailable: hostWithMDM.EncryptionKeyAvailable != nil && *hostWithMDM.EncryptionKeyAvailable,
 		}
 		return &host, nil
 	case errors.Is(err, sql.ErrNoRows):
@@ -2421,6 +2619,7 @@ func (ds *Datastore) SearchHosts(ctx context.Context, filter fleet.TeamFilter, m
     COALESCE(hd.percent_disk_space_available, 0) as percent_disk_space_available,
     COALESCE(hd.gigs_total_disk_space, 0) as gigs_total_disk_space,
     COALESCE(hst.seen_time, h.created_at) AS seen_time,
+    ` + hostChannelSeenTimesSelect + `,
 	COALESCE(hu.software_updated_at, h.created_at) AS software_updated_at
 	` + hostMDMSelect + `
   FROM hosts h
@@ -2509,6 +2708,43 @@ func (ds *Datastore) HostIDsByName(ctx context.Context, filter fleet.TeamFilter,
 	return hostIDs, nil
 }
 
+func (ds *Datastore) HostIDsByIdentifiers(ctx context.Context, filter fleet.TeamFilter, identifiers []string) ([]uint, error) {
+	if len(identifiers) == 0 {
+		return []uint{}, nil
+	}
+
+	sqlStatement := fmt.Sprintf(`
+			SELECT id FROM hosts
+			WHERE (hostname IN (?) OR uuid IN (?) OR hardware_serial IN (?)) AND %s
+		`, ds.whereFilterHostsByTeams(filter, "hosts"),
+	)
+
+	// each identifier is used 3 times in the query, batch them to stay under
+	// the MySQL limit of placeholders.
+	const batchSize = 10000
+	var hostIDs []uint
+	for len(identifiers) > 0 {
+		batch := identifiers
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		identifiers = identifiers[len(batch):]
+
+		sql, args, err := sqlx.In(sqlStatement, batch, batch, batch)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "building query to get host IDs by identifiers")
+		}
+
+		var batchIDs []uint
+		if err := sqlx.SelectContext(ctx, ds.reader(ctx), &batchIDs, sql, args...); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get host IDs by identifiers")
+		}
+		hostIDs = append(hostIDs, batchIDs...)
+	}
+
+	return hostIDs, nil
+}
+
 func (ds *Datastore) ListHostsLiteByUUIDs(ctx context.Context, filter fleet.TeamFilter, uuids []string) ([]*fleet.Host, error) {
 	if len(uuids) == 0 {
 		return nil, nil
@@ -2909,32 +3145,38 @@ func (ds *Datastore) CleanupExpiredHosts(ctx context.Context) ([]uint, error) {
 		return nil, ctxerr.Wrap(ctx, err, "getting app config")
 	}
 
-	query := `SELECT id, config from teams`
-	var teams []*fleet.Team
+	// the teams of a tenant that overrides the host expiry settings use the
+	// tenant's settings instead of the global ones.
+	query := `
+		SELECT t.id, t.config, tn.config AS tenant_config
+		FROM teams t
+		LEFT JOIN tenants tn ON tn.id = t.tenant_id`
+	var teams []struct {
+		ID           uint               `db:"id"`
+		Config       fleet.TeamConfig   `db:"config"`
+		TenantConfig fleet.TenantConfig `db:"tenant_config"`
+	}
 	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &teams, query); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "get teams")
 	}
 
-	teamHostExpiryEnabled := false
-	for _, team := range teams {
-		if team.Config.HostExpirySettings.HostExpiryEnabled {
-			teamHostExpiryEnabled = true
-			break
-		}
-	}
-	if !ac.HostExpirySettings.HostExpiryEnabled && !teamHostExpiryEnabled {
-		return nil, nil
-	}
-
 	var teamsUsingGlobalExpiry []uint
 	teamsUsingCustomExpiry := map[uint]int{}
 	for _, team := range teams {
-		if !team.Config.HostExpirySettings.HostExpiryEnabled {
-			teamsUsingGlobalExpiry = append(teamsUsingGlobalExpiry, team.ID)
-		} else {
+		switch {
+		case team.Config.HostExpirySettings.HostExpiryEnabled:
 			teamsUsingCustomExpiry[team.ID] = team.Config.HostExpirySettings.HostExpiryWindow
+		case team.TenantConfig.HostExpirySettings != nil:
+			if team.TenantConfig.HostExpirySettings.HostExpiryEnabled {
+				teamsUsingCustomExpiry[team.ID] = team.TenantConfig.HostExpirySettings.HostExpiryWindow
+			}
+		default:
+			teamsUsingGlobalExpiry = append(teamsUsingGlobalExpiry, team.ID)
 		}
 	}
+	if !ac.HostExpirySettings.HostExpiryEnabled && len(teamsUsingCustomExpiry) == 0 {
+		return nil, nil
+	}
 
 	// Usual clean up queries used to be like this:
 	// DELETE FROM hosts WHERE id in (SELECT host_id FROM host_seen_times WHERE seen_time < DATE_SUB(NOW(), INTERVAL ? DAY))
@@ -3685,7 +3927,7 @@ func (ds *Datastore) GetHostDiskEncryptionKey(ctx context.Context, hostID uint)
 	var key fleet.HostDiskEncryptionKey
 	err := sqlx.GetContext(ctx, ds.reader(ctx), &key, `
           SELECT
-            host_id, base64_encrypted, decryptable, updated_at
+            host_id, base64_encrypted, decryptable, key_slot, updated_at
           FROM
             host_disk_encryption_keys
           WHERE host_id = ?`, hostID)
@@ -3699,14 +3941,110 @@ func (ds *Datastore) GetHostDiskEncryptionKey(ctx context.Context, hostID uint)
 	return &key, nil
 }
 
+func (ds *Datastore) SetHostDiskEncryptionKeyRotationPending(ctx context.Context, hostID uint, cmdUUID string) (bool, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+          UPDATE host_disk_encryption_keys
+          SET rotate_command_uuid = ?
+          WHERE host_id = ? AND rotate_command_uuid IS NULL`, cmdUUID, hostID)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "set host disk encryption key rotation pending")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ds *Datastore) UpdateHostDiskEncryptionKeyFromRotateResult(ctx context.Context, hostUUID, cmdUUID, encryptedBase64Key string) error {
+	stmt := `
+          UPDATE host_disk_encryption_keys hdek
+            JOIN hosts h ON h.id = hdek.host_id
+          SET
+            hdek.rotate_command_uuid = NULL`
+	var args []any
+	if encryptedBase64Key != "" {
+		// the new key is verified again by the disk encryption keys cron
+		stmt += `,
+            hdek.base64_encrypted = ?,
+            hdek.decryptable = NULL,
+            hdek.client_error = ''`
+		args = append(args, encryptedBase64Key)
+	}
+	stmt += `
+          WHERE h.uuid = ? AND hdek.rotate_command_uuid = ?`
+	args = append(args, hostUUID, cmdUUID)
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host disk encryption key from rotate result")
+	}
+	return nil
+}
+
+func (ds *Datastore) SaveLUKSDiskEncryptionKey(ctx context.Context, hostID uint, passphrase string, keySlot *uint, clientError string) error {
+	if clientError != "" {
+		// keep the previously escrowed passphrase, if any, it is still valid
+		const stmt = `
+          INSERT INTO host_disk_encryption_keys (host_id, base64_encrypted, client_error)
+            VALUES (?, '', ?)
+          ON DUPLICATE KEY UPDATE
+            client_error = VALUES(client_error)`
+		if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, clientError); err != nil {
+			return ctxerr.Wrap(ctx, err, "save LUKS disk encryption key client error")
+		}
+		return nil
+	}
+
+	encrypted, err := encrypt([]byte(passphrase), ds.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypting LUKS disk encryption key")
+	}
+
+	// the passphrase is encrypted server-side, so it is always decryptable
+	const stmt = `
+          INSERT INTO host_disk_encryption_keys
+            (host_id, base64_encrypted, decryptable, key_slot, client_error, reset_requested)
+          VALUES
+            (?, ?, 1, ?, '', 0)
+          ON DUPLICATE KEY UPDATE
+            base64_encrypted = VALUES(base64_encrypted),
+            decryptable = VALUES(decryptable),
+            key_slot = VALUES(key_slot),
+            client_error = VALUES(client_error),
+            reset_requested = VALUES(reset_requested)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, base64.StdEncoding.EncodeToString(encrypted), keySlot); err != nil {
+		return ctxerr.Wrap(ctx, err, "save LUKS disk encryption key")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetHostLUKSDiskEncryptionKey(ctx context.Context, hostID uint) (*fleet.HostDiskEncryptionKey, error) {
+	key, err := ds.GetHostDiskEncryptionKey(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	if key.Base64Encrypted == "" || key.Decryptable == nil || !*key.Decryptable {
+		msg := fmt.Sprintf("for host %d", hostID)
+		return nil, ctxerr.Wrap(ctx, notFound("HostDiskEncryptionKey").WithMessage(msg))
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(key.Base64Encrypted)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decoding LUKS disk encryption key")
+	}
+	decrypted, err := decrypt(encrypted, ds.serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decrypting LUKS disk encryption key")
+	}
+	key.DecryptedValue = string(decrypted)
+	return key, nil
+}
+
 func (ds *Datastore) SetOrUpdateHostOrbitInfo(
-	ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool,
+	ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool, timezone sql.NullString,
 ) error {
 	return ds.updateOrInsert(
 		ctx,
-		`UPDATE host_orbit_info SET version = ?, desktop_version = ?, scripts_enabled = ? WHERE host_id = ?`,
-		`INSERT INTO host_orbit_info (version, desktop_version, scripts_enabled, host_id) VALUES (?, ?, ?, ?)`,
-		version, desktopVersion, scriptsEnabled, hostID,
+		`UPDATE host_orbit_info SET version = ?, desktop_version = ?, scripts_enabled = ?, timezone = ? WHERE host_id = ?`,
+		`INSERT INTO host_orbit_info (version, desktop_version, scripts_enabled, timezone, host_id) VALUES (?, ?, ?, ?, ?)`,
+		version, desktopVersion, scriptsEnabled, timezone, hostID,
 	)
 }
 
@@ -3717,7 +4055,8 @@ func (ds *Datastore) GetHostOrbitInfo(ctx context.Context, hostID uint) (*fleet.
 	SELECT
 		version,
 		desktop_version,
-		scripts_enabled
+		scripts_enabled,
+		timezone
 	FROM
 		host_orbit_info
 	WHERE host_id = ?`, hostID,
@@ -3783,6 +4122,7 @@ func (ds *Datastore) GetHostMDMCheckinInfo(ctx context.Context, hostUUID string)
 	err := sqlx.GetContext(ctx, ds.writer(ctx), &hmdm, `
 		SELECT
 			h.hardware_serial,
+			h.platform,
 			COALESCE(hm.installed_from_dep, false) as installed_from_dep,
 			hd.display_name,
 			COALESCE(h.team_id, 0) as team_id,
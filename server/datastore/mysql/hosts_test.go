@@ -83,6 +83,8 @@ func TestHosts(t *testing.T) {
 		{"ListFilterAdditional", testHostsListFilterAdditional},
 		{"ListStatus", testHostsListStatus},
 		{"ListQuery", testHostsListQuery},
+		{"Stream", testHostsStream},
+		{"ListChanges", testHostsListChanges},
 		{"ListMDM", testHostsListMDM},
 		{"SelectHostMDM", testHostMDMSelect},
 		{"ListMunkiIssueID", testHostsListMunkiIssueID},
@@ -138,6 +140,7 @@ func TestHosts(t *testing.T) {
 		{"SetOrUpdateDeviceAuthToken", testHostsSetOrUpdateDeviceAuthToken},
 		{"OSVersions", testOSVersions},
 		{"DeleteHosts", testHostsDeleteHosts},
+		{"CleanupOrphanedHostRows", testHostsCleanupOrphanedHostRows},
 		{"HostIDsByOSVersion", testHostIDsByOSVersion},
 		{"ReplaceHostBatteries", testHostsReplaceHostBatteries},
 		{"ReplaceHostBatteriesDeadlock", testHostsReplaceHostBatteriesDeadlock},
@@ -153,6 +156,8 @@ func TestHosts(t *testing.T) {
 		{"LoadHostByOrbitNodeKey", testHostsLoadHostByOrbitNodeKey},
 		{"SetOrUpdateHostDiskEncryptionKeys", testHostsSetOrUpdateHostDisksEncryptionKey},
 		{"SetHostsDiskEncryptionKeyStatus", testHostsSetDiskEncryptionKeyStatus},
+		{"DiskEncryptionKeyRotation", testHostsDiskEncryptionKeyRotation},
+		{"SaveLUKSDiskEncryptionKey", testHostsSaveLUKSDiskEncryptionKey},
 		{"GetUnverifiedDiskEncryptionKeys", testHostsGetUnverifiedDiskEncryptionKeys},
 		{"EnrollOrbit", testHostsEnrollOrbit},
 		{"EnrollUpdatesMissingInfo", testHostsEnrollUpdatesMissingInfo},
@@ -216,6 +221,7 @@ func testUpdateHost(t *testing.T, ds *Datastore, updateHostFunc func(context.Con
 	assert.Nil(t, host.OrbitVersion)
 	assert.Nil(t, host.DesktopVersion)
 	assert.Nil(t, host.ScriptsEnabled)
+	assert.Nil(t, host.Timezone)
 
 	additionalJSON := json.RawMessage(`{"foobar": "bim"}`)
 	err = ds.SaveHostAdditional(context.Background(), host.ID, &additionalJSON)
@@ -227,7 +233,7 @@ func testUpdateHost(t *testing.T, ds *Datastore, updateHostFunc func(context.Con
 	)
 	err = ds.SetOrUpdateHostOrbitInfo(
 		context.Background(), host.ID, orbitVersion, sql.NullString{String: desktopVersion, Valid: true},
-		sql.NullBool{Bool: true, Valid: true},
+		sql.NullBool{Bool: true, Valid: true}, sql.NullString{String: "America/New_York", Valid: true},
 	)
 	require.NoError(t, err)
 
@@ -239,6 +245,7 @@ func testUpdateHost(t *testing.T, ds *Datastore, updateHostFunc func(context.Con
 	assert.Equal(t, orbitVersion, *host.OrbitVersion)
 	assert.Equal(t, desktopVersion, *host.DesktopVersion)
 	assert.True(t, *host.ScriptsEnabled)
+	assert.Equal(t, "America/New_York", *host.Timezone)
 
 	err = updateHostFunc(context.Background(), host)
 	require.NoError(t, err)
@@ -248,7 +255,7 @@ func testUpdateHost(t *testing.T, ds *Datastore, updateHostFunc func(context.Con
 	require.NoError(t, err)
 
 	err = ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Valid: false},
+		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Valid: false}, sql.NullString{},
 	)
 	require.NoError(t, err)
 
@@ -871,6 +878,130 @@ func testHostListOptionsTeamFilter(t *testing.T, ds *Datastore) {
 	require.True(t, strings.Contains(err.Error(), "team is invalid"), err)
 }
 
+func testHostsStream(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("foo-%d", i)
+		if i == 2 {
+			name = fmt.Sprintf("bar-%d", i)
+		}
+		hosts = append(hosts, test.NewHost(t, ds, name, "", name, name, time.Now()))
+	}
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	stream := func(opt fleet.HostListOptions, batchSize int) [][]uint {
+		var batches [][]uint
+		err := ds.StreamHosts(ctx, filter, opt, batchSize, func(batch []*fleet.Host) error {
+			ids := make([]uint, 0, len(batch))
+			for _, h := range batch {
+				ids = append(ids, h.ID)
+			}
+			batches = append(batches, ids)
+			return nil
+		})
+		require.NoError(t, err)
+		return batches
+	}
+
+	require.Equal(t, [][]uint{
+		{hosts[0].ID, hosts[1].ID},
+		{hosts[2].ID, hosts[3].ID},
+		{hosts[4].ID},
+	}, stream(fleet.HostListOptions{}, 2))
+	// an exact multiple of the batch size ends with an empty query
+	require.Equal(t, [][]uint{
+		{hosts[0].ID, hosts[1].ID, hosts[2].ID, hosts[3].ID, hosts[4].ID},
+	}, stream(fleet.HostListOptions{}, 5))
+
+	// the pagination and ordering options are ignored, the query is applied
+	require.Equal(t, [][]uint{
+		{hosts[0].ID, hosts[1].ID, hosts[3].ID},
+		{hosts[4].ID},
+	}, stream(fleet.HostListOptions{ListOptions: fleet.ListOptions{
+		MatchQuery:     "foo",
+		Page:           1,
+		PerPage:        1,
+		OrderKey:       "hostname",
+		OrderDirection: fleet.OrderDescending,
+	}}, 3))
+
+	// streaming stops at the first error
+	var calls int
+	err := ds.StreamHosts(ctx, filter, fleet.HostListOptions{}, 1, func(batch []*fleet.Host) error {
+		calls++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, calls)
+}
+
+func testHostsListChanges(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	h1 := test.NewHost(t, ds, "h1", "", "h1", "h1", time.Now())
+	h2 := test.NewHost(t, ds, "h2", "", "h2", "h2", time.Now())
+	h3 := test.NewHost(t, ds, "h3", "", "h3", "h3", time.Now())
+	h4 := test.NewHost(t, ds, "h4", "", "h4", "h4", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{h2.ID}))
+
+	// h1 and h2 are updated during the same second, h3 after them and h4
+	// during the current second.
+	t1 := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	t2 := t1.Add(time.Minute)
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		if _, err := q.ExecContext(ctx, `UPDATE hosts SET updated_at = ? WHERE id IN (?, ?)`, t1, h1.ID, h2.ID); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `UPDATE hosts SET updated_at = ? WHERE id = ?`, t2, h3.ID); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `UPDATE hosts SET updated_at = NOW() WHERE id = ?`, h4.ID)
+		return err
+	})
+
+	ids := func(hosts []*fleet.HostChange) []uint {
+		res := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			res = append(res, h.ID)
+		}
+		return res
+	}
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hosts, err := ds.ListHostChanges(ctx, filter, t1.Add(-time.Second), 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID, h3.ID}, ids(hosts))
+	require.Equal(t, "h2", hosts[1].Hostname)
+	require.Equal(t, team.ID, *hosts[1].TeamID)
+	require.Equal(t, "team1", *hosts[1].TeamName)
+	require.Nil(t, hosts[0].TeamID)
+	require.True(t, hosts[0].UpdatedAt.Equal(t1))
+
+	// resume after h1
+	hosts, err = ds.ListHostChanges(ctx, filter, t1, h1.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{h2.ID, h3.ID}, ids(hosts))
+
+	hosts, err = ds.ListHostChanges(ctx, filter, t1.Add(-time.Second), 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint{h1.ID, h2.ID}, ids(hosts))
+
+	hosts, err = ds.ListHostChanges(ctx, filter, t2, h3.ID, 10)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	// a team user only sees the hosts of its teams
+	teamFilter := fleet.TeamFilter{User: &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}, IncludeObserver: true}
+	hosts, err = ds.ListHostChanges(ctx, teamFilter, t1.Add(-time.Second), 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint{h2.ID}, ids(hosts))
+}
+
 func testHostsListFilterAdditional(t *testing.T, ds *Datastore) {
 	h, err := ds.NewHost(context.Background(), &fleet.Host{
 		DetailUpdatedAt: time.Now(),
@@ -3035,6 +3166,19 @@ func testHostsListBySoftwareChangedAt(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	require.Len(t, foundHosts, 1)
 	require.Greater(t, foundHosts[0].SoftwareUpdatedAt, foundHosts[0].CreatedAt)
+
+	// marking the software of host1 as refreshed makes it the latest updated
+	time.Sleep(1 * time.Second)
+	err = ds.UpdateHostSoftwareUpdatedAt(context.Background(), host1.ID)
+	require.NoError(t, err)
+
+	hosts, err = ds.ListHosts(context.Background(), filter, fleet.HostListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "software_updated_at", OrderDirection: fleet.OrderDescending},
+	})
+	require.NoError(t, err)
+	require.Len(t, hosts, 10)
+	require.Equal(t, host1.ID, hosts[0].ID)
+	require.Equal(t, host2.ID, hosts[1].ID)
 }
 
 func testHostsListByOperatingSystemID(t *testing.T, ds *Datastore) {
@@ -6405,6 +6549,46 @@ func testOSVersions(t *testing.T, ds *Datastore) {
 	require.Equal(t, expected, osVersions.OSVersions)
 }
 
+func testHostsCleanupOrphanedHostRows(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	host, err := ds.NewHost(ctx, &fleet.Host{
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		PolicyUpdatedAt: time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         ptr.String("1"),
+		UUID:            "1",
+		Hostname:        "foo.local",
+	})
+	require.NoError(t, err)
+
+	const deletedHostID = 999_999
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO label_membership (label_id, host_id) VALUES (1, ?), (2, ?), (1, ?)`, host.ID, deletedHostID, deletedHostID)
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `INSERT INTO host_seen_times (host_id, seen_time) VALUES (?, NOW())`, deletedHostID)
+		return err
+	})
+
+	n, err := ds.CleanupOrphanedHostRows(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+
+	var hostIDs []uint
+	require.NoError(t, sqlx.SelectContext(ctx, ds.reader(ctx), &hostIDs, `SELECT host_id FROM label_membership`))
+	require.Equal(t, []uint{host.ID}, hostIDs)
+	var seenHostIDs []uint
+	require.NoError(t, sqlx.SelectContext(ctx, ds.reader(ctx), &seenHostIDs, `SELECT host_id FROM host_seen_times`))
+	require.Equal(t, []uint{host.ID}, seenHostIDs)
+
+	n, err = ds.CleanupOrphanedHostRows(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
 func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	// Updates hosts and host_seen_times.
 	host, err := ds.NewHost(context.Background(), &fleet.Host{
@@ -6542,7 +6726,7 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	// set host orbit info
 	err = ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, "1.1.0", sql.NullString{String: "2.1.0", Valid: true}, sql.NullBool{Bool: true, Valid: true},
+		context.Background(), host.ID, "1.1.0", sql.NullString{String: "2.1.0", Valid: true}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	// set an encryption key
@@ -6577,6 +6761,8 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	err = ds.RecordHostBootstrapPackage(context.Background(), "command-uuid", host.UUID)
 	require.NoError(t, err)
+	err = ds.RecordHostFleetdInstall(context.Background(), "command-uuid", host.UUID, 0)
+	require.NoError(t, err)
 	_, err = ds.NewHostScriptExecutionRequest(context.Background(), &fleet.HostScriptRequestPayload{HostID: host.ID, ScriptContents: "foo"})
 	require.NoError(t, err)
 
@@ -6592,6 +6778,9 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	`, host.UUID)
 	require.NoError(t, err)
 
+	err = ds.RecordHostMDMAppleProfileCertificate(context.Background(), host.UUID, "a-profile-uuid")
+	require.NoError(t, err)
+
 	err = ds.NewActivity( // automatically creates the host_activities entry
 		context.Background(),
 		user1,
@@ -6626,6 +6815,80 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 			`, host.ID, calendarEventID)
 	require.NoError(t, err)
 
+	// Add the host to a host group.
+	hostGroup, err := ds.NewHostGroup(context.Background(), &fleet.HostGroup{Name: "delete-hosts-group"})
+	require.NoError(t, err)
+	err = ds.AddHostsToHostGroup(context.Background(), hostGroup.ID, []uint{host.ID})
+	require.NoError(t, err)
+
+	// Queue a scheduled script on the host.
+	script, err := ds.NewScript(context.Background(), &fleet.Script{Name: "delete-hosts-script.sh", ScriptContents: "echo"})
+	require.NoError(t, err)
+	schedule, err := ds.NewScriptSchedule(context.Background(), &fleet.ScriptSchedule{ScriptID: script.ID, LocalTime: "02:00"})
+	require.NoError(t, err)
+	_, err = ds.NewScheduledHostScriptExecutionRequest(context.Background(), &fleet.HostScriptRequestPayload{
+		HostID: host.ID, ScriptID: &script.ID, ScriptContentID: script.ScriptContentID,
+	}, schedule.ID, "2024-05-02")
+	require.NoError(t, err)
+
+	// Mark the host as pending enrollment approval.
+	_, err = ds.writer(context.Background()).Exec(`INSERT INTO host_pending_approvals (host_id, source) VALUES (?, ?)`, host.ID, fleet.HostEnrollmentSourceOrbit)
+	require.NoError(t, err)
+
+	// Add a certificate to the host.
+	err = ds.ReplaceHostCertificates(context.Background(), host.ID, fleet.HostCertificateOriginOsquery, []*fleet.HostCertificate{
+		{HostID: host.ID, SHA1: "0123456789abcdef0123456789abcdef01234567", NotValidBefore: time.Now(), NotValidAfter: time.Now().Add(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	// Add a lock request waiting for approval to the host.
+	err = ds.NewHostLockWipeApprovalRequest(context.Background(), host.ID, fleet.HostLockWipeActionLock, user1.ID)
+	require.NoError(t, err)
+
+	// Add notes and a custom field value to the host.
+	err = ds.UpdateHostCustomFields(context.Background(), host.ID, fleet.HostCustomFieldsPayload{
+		Notes:        ptr.String("some notes"),
+		CustomFields: map[string]*string{"owner": ptr.String("foo@example.com")},
+	})
+	require.NoError(t, err)
+
+	// Record the warranty of the host.
+	err = ds.UpsertHostWarranties(context.Background(), []*fleet.HostWarranty{{HostID: host.ID, Vendor: fleet.WarrantyVendorDell}})
+	require.NoError(t, err)
+
+	// Record the health score of the host.
+	err = ds.UpsertHostHealthScores(context.Background(), []*fleet.HostHealthScore{{HostID: host.ID, Score: 100}})
+	require.NoError(t, err)
+
+	// Record the cloud instance of the host.
+	err = ds.SetOrUpdateHostCloudInstance(context.Background(), host.ID, &fleet.HostCloudInstance{Provider: fleet.CloudProviderAWS, InstanceID: "i-0abc"})
+	require.NoError(t, err)
+
+	// Record the security information reported by the host via MDM.
+	err = ds.SetOrUpdateHostMDMAppleSecurityInfo(context.Background(), host.ID, &fleet.HostMDMAppleSecurityInfo{SIPEnabled: ptr.Bool(true)})
+	require.NoError(t, err)
+
+	// Put the host in Lost Mode, record its location, escrow its Activation
+	// Lock bypass code and set its Recovery Lock password.
+	err = ds.NewHostLostMode(context.Background(), host.ID, fleet.HostLostModeSettings{Message: "lost"}, "lost-mode-cmd")
+	require.NoError(t, err)
+	err = ds.NewHostLocation(context.Background(), &fleet.HostMDMAppleLocation{HostID: host.ID, CommandUUID: "location-cmd"})
+	require.NoError(t, err)
+	err = ds.SetHostMDMAppleActivationLockBypassCode(context.Background(), host.UUID, "bypass-code")
+	require.NoError(t, err)
+	err = ds.SetHostMDMAppleRecoveryLockPending(context.Background(), host.ID, "recovery-lock", "set-recovery-lock-cmd")
+	require.NoError(t, err)
+
+	// Record the state of the Linux disk encryption enforcement of the host.
+	err = ds.SetHostLinuxDiskEncryptionEnforcement(context.Background(), host.ID, fleet.LinuxDiskEncryptionEnforcementActionRequired, "")
+	require.NoError(t, err)
+
+	_, err = ds.writer(context.Background()).Exec(`INSERT INTO host_mdm_apple_firmware_passwords (host_id, set_command_uuid) VALUES (?, ?)`, host.ID, "set-firmware-password-cmd")
+	require.NoError(t, err)
+
+	_, err = ds.writer(context.Background()).Exec(`INSERT INTO host_cve_detections (host_id, cve) VALUES (?, ?)`, host.ID, "CVE-2024-0001")
+	require.NoError(t, err)
+
 	// Check there's an entry for the host in all the associated tables.
 	for _, hostRef := range hostRefs {
 		var ok bool
@@ -7492,6 +7755,14 @@ func testHostsLoadHostByOrbitNodeKey(t *testing.T, ds *Datastore) {
 	require.True(t, loadSimple.IsOsqueryEnrolled())
 	require.False(t, loadSimple.MDMInfo.IsPendingDEPFleetEnrollment())
 	require.False(t, loadSimple.IsEligibleForDEPMigration())
+	require.Nil(t, loadSimple.Timezone)
+
+	// the timezone reported by fleetd is loaded with the host
+	err = ds.SetOrUpdateHostOrbitInfo(ctx, hSimple.ID, "1.24.0", sql.NullString{}, sql.NullBool{}, sql.NullString{String: "Asia/Tokyo", Valid: true})
+	require.NoError(t, err)
+	loadSimple, err = ds.LoadHostByOrbitNodeKey(ctx, *hSimple.OrbitNodeKey)
+	require.NoError(t, err)
+	require.Equal(t, "Asia/Tokyo", *loadSimple.Timezone)
 
 	// create a host that will be pending enrollment in Fleet MDM
 	hFleet := createOrbitHost("fleet")
@@ -7674,6 +7945,105 @@ func testHostsSetOrUpdateHostDisksEncryptionKey(t *testing.T, ds *Datastore) {
 	checkEncryptionKeyStatus(t, ds, host3.ID, "", ptr.Bool(false))
 }
 
+func testHostsDiskEncryptionKeyRotation(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "mac", "", "mackey", "mac-uuid", time.Now(), test.WithPlatform("darwin"))
+
+	// no escrowed key to rotate
+	pending, err := ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, "rotate-0")
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	err = ds.SetOrUpdateHostDiskEncryptionKey(ctx, host.ID, "AAA", "", nil)
+	require.NoError(t, err)
+	err = ds.SetHostsDiskEncryptionKeyStatus(ctx, []uint{host.ID}, true, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	pending, err = ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, "rotate-1")
+	require.NoError(t, err)
+	require.True(t, pending)
+	// a single rotation is pending at a time
+	pending, err = ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, "rotate-2")
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	// the key reported by the host is ingested while the rotation is pending
+	err = ds.SetOrUpdateHostDiskEncryptionKey(ctx, host.ID, "AAA", "", nil)
+	require.NoError(t, err)
+	checkEncryptionKeyStatus(t, ds, host.ID, "AAA", ptr.Bool(true))
+
+	// the result of another command is ignored
+	err = ds.UpdateHostDiskEncryptionKeyFromRotateResult(ctx, host.UUID, "rotate-2", "BBB")
+	require.NoError(t, err)
+	checkEncryptionKeyStatus(t, ds, host.ID, "AAA", ptr.Bool(true))
+
+	// the new key must be verified again
+	err = ds.UpdateHostDiskEncryptionKeyFromRotateResult(ctx, host.UUID, "rotate-1", "BBB")
+	require.NoError(t, err)
+	checkEncryptionKeyStatus(t, ds, host.ID, "BBB", nil)
+
+	// a failed rotation keeps the current key
+	pending, err = ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, "rotate-3")
+	require.NoError(t, err)
+	require.True(t, pending)
+	err = ds.UpdateHostDiskEncryptionKeyFromRotateResult(ctx, host.UUID, "rotate-3", "")
+	require.NoError(t, err)
+	checkEncryptionKeyStatus(t, ds, host.ID, "BBB", nil)
+	pending, err = ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, "rotate-4")
+	require.NoError(t, err)
+	require.True(t, pending)
+}
+
+func testHostsSaveLUKSDiskEncryptionKey(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host, err := ds.EnrollHost(ctx, false, "linux1-uuid", "linux1-uuid", "", "linux1key", nil, 0)
+	require.NoError(t, err)
+	orbitKey := uuid.New().String()
+	_, err = ds.EnrollOrbit(ctx, false, fleet.OrbitHostInfo{HardwareUUID: "linux1-uuid"}, orbitKey, nil)
+	require.NoError(t, err)
+
+	_, err = ds.GetHostLUKSDiskEncryptionKey(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// a client error without a previous key does not make a key available
+	err = ds.SaveLUKSDiskEncryptionKey(ctx, host.ID, "", nil, "cryptsetup failed")
+	require.NoError(t, err)
+	_, err = ds.GetHostLUKSDiskEncryptionKey(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.SetDiskEncryptionResetStatus(ctx, host.ID, true))
+	err = ds.SaveLUKSDiskEncryptionKey(ctx, host.ID, "passphrase1", ptr.Uint(1), "")
+	require.NoError(t, err)
+	key, err := ds.GetHostLUKSDiskEncryptionKey(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "passphrase1", key.DecryptedValue)
+	require.Equal(t, ptr.Uint(1), key.KeySlot)
+	require.NotContains(t, key.Base64Encrypted, "passphrase1")
+
+	// escrowing a key clears the reset request and makes the key available
+	h, err := ds.LoadHostByOrbitNodeKey(ctx, orbitKey)
+	require.NoError(t, err)
+	require.True(t, h.MDM.EncryptionKeyAvailable)
+	require.NotNil(t, h.DiskEncryptionResetRequested)
+	require.False(t, *h.DiskEncryptionResetRequested)
+
+	// a client error keeps the escrowed passphrase
+	err = ds.SaveLUKSDiskEncryptionKey(ctx, host.ID, "", nil, "rotation failed")
+	require.NoError(t, err)
+	key, err = ds.GetHostLUKSDiskEncryptionKey(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "passphrase1", key.DecryptedValue)
+	require.Equal(t, ptr.Uint(1), key.KeySlot)
+
+	// a rotated passphrase replaces the previous one
+	err = ds.SaveLUKSDiskEncryptionKey(ctx, host.ID, "passphrase2", ptr.Uint(2), "")
+	require.NoError(t, err)
+	key, err = ds.GetHostLUKSDiskEncryptionKey(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "passphrase2", key.DecryptedValue)
+	require.Equal(t, ptr.Uint(2), key.KeySlot)
+}
+
 func testHostsSetDiskEncryptionKeyStatus(t *testing.T, ds *Datastore) {
 	ctx := context.Background()
 	host, err := ds.NewHost(context.Background(), &fleet.Host{
@@ -8781,7 +9151,7 @@ func testGetHostOrbitInfo(t *testing.T, ds *Datastore) {
 
 	orbitVersion := "1.1.0"
 	err = ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Valid: false},
+		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Valid: false}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	hostOrbitInfo, err := ds.GetHostOrbitInfo(context.Background(), host.ID)
@@ -8789,7 +9159,7 @@ func testGetHostOrbitInfo(t *testing.T, ds *Datastore) {
 	assert.Nil(t, hostOrbitInfo.ScriptsEnabled)
 
 	err = ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Bool: true, Valid: true},
+		context.Background(), host.ID, orbitVersion, sql.NullString{Valid: false}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	hostOrbitInfo, err = ds.GetHostOrbitInfo(context.Background(), host.ID)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -21,12 +22,12 @@ func (ds *Datastore) NewInvite(ctx context.Context, i *fleet.Invite) (*fleet.Inv
 
 	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
 		sqlStmt := `
-	INSERT INTO invites ( invited_by, email, name, position, token, sso_enabled, global_role )
-	  VALUES ( ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO invites ( invited_by, email, name, position, token, sso_enabled, global_role, tenant_id )
+	  VALUES ( ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 		result, err := tx.ExecContext(ctx, sqlStmt, i.InvitedBy, i.Email,
-			i.Name, i.Position, i.Token, i.SSOEnabled, i.GlobalRole)
+			i.Name, i.Position, i.Token, i.SSOEnabled, i.GlobalRole, i.TenantID)
 		if err != nil && isDuplicate(err) {
 			return ctxerr.Wrap(ctx, alreadyExists("Invite", i.Email))
 		} else if err != nil {
@@ -206,3 +207,13 @@ func (ds *Datastore) UpdateInvite(ctx context.Context, id uint, i *fleet.Invite)
 		return nil
 	})
 }
+
+func (ds *Datastore) CleanupExpiredInvites(ctx context.Context, createdBefore time.Time) (int64, error) {
+	// the teams of the invites are deleted by the foreign key cascade.
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM invites WHERE created_at < ?`, createdBefore)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "deleting expired invites")
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
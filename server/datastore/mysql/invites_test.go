@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
@@ -26,6 +28,7 @@ func TestInvites(t *testing.T) {
 		{"ByEmail", testInvitesByEmail},
 		{"Invite", testInvitesInvite},
 		{"Update", testInvitesUpdate},
+		{"CleanupExpired", testInvitesCleanupExpired},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -290,3 +293,46 @@ func testInvitesUpdate(t *testing.T, ds *Datastore) {
 	assert.Equal(t, fleet.RoleAdmin, verify.Teams[0].Role)
 
 }
+
+func testInvitesCleanupExpired(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team"})
+	require.NoError(t, err)
+
+	expired, err := ds.NewInvite(ctx, &fleet.Invite{
+		Email: "expired@example.com",
+		Name:  "expired",
+		Token: "expired",
+		Teams: []fleet.UserTeam{{Role: fleet.RoleObserver, Team: fleet.Team{ID: team.ID}}},
+	})
+	require.NoError(t, err)
+	valid, err := ds.NewInvite(ctx, &fleet.Invite{
+		Email: "valid@example.com",
+		Name:  "valid",
+		Token: "valid",
+	})
+	require.NoError(t, err)
+
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE invites SET created_at = DATE_SUB(NOW(), INTERVAL 10 DAY) WHERE id = ?`, expired.ID)
+		return err
+	})
+
+	n, err := ds.CleanupExpiredInvites(ctx, time.Now().Add(-5*24*time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	_, err = ds.Invite(ctx, expired.ID)
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.Invite(ctx, valid.ID)
+	require.NoError(t, err)
+
+	var teamCount int
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &teamCount, `SELECT COUNT(*) FROM invite_teams WHERE invite_id = ?`, expired.ID))
+	require.Zero(t, teamCount)
+
+	n, err = ds.CleanupExpiredInvites(ctx, time.Now().Add(-5*24*time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
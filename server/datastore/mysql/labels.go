@@ -531,6 +531,8 @@ func (ds *Datastore) ListHostsInLabel(ctx context.Context, filter fleet.TeamFilt
       %s
       %s
 			%s
+      %s
+      %s
     FROM label_membership lm
     JOIN hosts h ON (lm.host_id = h.id)
     LEFT JOIN host_seen_times hst ON (h.id=hst.host_id)
@@ -572,7 +574,17 @@ func (ds *Datastore) ListHostsInLabel(ctx context.Context, filter fleet.TeamFilt
 	COALESCE(dm.device_mapping, 'null') as device_mapping`
 	}
 
-	query := fmt.Sprintf(queryFmt, hostMDMSelect, failingPoliciesSelect, deviceMappingSelect, hostMDMJoin, failingPoliciesJoin, deviceMappingJoin)
+	var customFieldsSelect string
+	if opt.PopulateCustomFields {
+		customFieldsSelect = `,` + hostCustomFieldsSelect
+	}
+
+	var warrantySelect string
+	if opt.PopulateWarranty {
+		warrantySelect = `,` + hostWarrantySelect
+	}
+
+	query := fmt.Sprintf(queryFmt, hostMDMSelect, failingPoliciesSelect, deviceMappingSelect, customFieldsSelect, warrantySelect, hostMDMJoin, failingPoliciesJoin, deviceMappingJoin)
 
 	query, params, err := ds.applyHostLabelFilters(ctx, filter, lid, query, opt)
 	if err != nil {
@@ -604,6 +616,8 @@ func (ds *Datastore) applyHostLabelFilters(ctx context.Context, filter fleet.Tea
 	var err error
 	query, params = filterHostsByStatus(ds.clock.Now(), query, opt, params)
 	query, params = filterHostsByTeam(query, opt, params)
+	query, params = filterHostsByHostGroup(query, opt, params)
+	query, params = filterHostsByCustomField(query, opt, params)
 	query, params = filterHostsByMDM(query, opt, params)
 	query, params, err = filterHostsByMacOSSettingsStatus(query, opt, params)
 	if err != nil {
@@ -623,6 +637,7 @@ func (ds *Datastore) applyHostLabelFilters(ctx context.Context, filter fleet.Tea
 	}
 	// TODO: should search columns include display_name (requires join to host_display_names)?
 	query, params, _ = hostSearchLike(query, params, opt.MatchQuery, hostSearchColumns...)
+	query, params = hostSearchCustomFields(query, params, opt.MatchQuery)
 
 	query, params = appendListOptionsWithCursorToSQL(query, params, &opt.ListOptions)
 	return query, params, nil
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetHostLinuxDiskEncryptionEnforcement(ctx context.Context, hostID uint, status fleet.LinuxDiskEncryptionEnforcementStatus, detail string) error {
+	const stmt = `
+	INSERT INTO host_linux_disk_encryption_enforcement
+		(host_id, status, detail)
+	VALUES
+		(?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		status = VALUES(status),
+		detail = VALUES(detail)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, status, detail); err != nil {
+		return ctxerr.Wrap(ctx, err, "set host linux disk encryption enforcement")
+	}
+	return nil
+}
+
+// whereLinuxDiskEncryptionStatus returns the condition that matches the Linux
+// hosts in the disk encryption status. The hosts whose root volume is
+// encrypted must escrow its passphrase, which requires the user to enter
+// their current passphrase. The hosts whose root volume is not encrypted must
+// be encrypted by the user, as guided by orbit.
+func (ds *Datastore) whereLinuxDiskEncryptionStatus(status fleet.DiskEncryptionStatus) string {
+	const (
+		whereEncrypted    = `hd.encrypted = 1`
+		whereNotEncrypted = `hd.encrypted = 0`
+		whereKeyAvailable = `COALESCE(hdek.base64_encrypted != '' AND hdek.decryptable = 1, 0) = 1`
+		whereClientError  = `COALESCE(hdek.client_error != '', 0) = 1`
+	)
+
+	switch status {
+	case fleet.DiskEncryptionVerified:
+		return whereEncrypted + ` AND ` + whereKeyAvailable
+
+	case fleet.DiskEncryptionActionRequired:
+		return `(` + whereEncrypted + ` AND NOT ` + whereKeyAvailable + ` AND NOT ` + whereClientError + `)
+OR (` + whereNotEncrypted + ` AND hle.status = '` + string(fleet.LinuxDiskEncryptionEnforcementActionRequired) + `')`
+
+	case fleet.DiskEncryptionEnforcing:
+		// orbit didn't report the enforcement state yet
+		return whereNotEncrypted + ` AND hle.host_id IS NULL`
+
+	case fleet.DiskEncryptionFailed:
+		return `(` + whereEncrypted + ` AND NOT ` + whereKeyAvailable + ` AND ` + whereClientError + `)
+OR (` + whereNotEncrypted + ` AND hle.status = '` + string(fleet.LinuxDiskEncryptionEnforcementFailed) + `')`
+
+	default:
+		return "FALSE"
+	}
+}
+
+func (ds *Datastore) GetMDMLinuxDiskEncryptionSummary(ctx context.Context, teamID *uint) (*fleet.MDMLinuxDiskEncryptionSummary, error) {
+	enabled, err := ds.getConfigEnableDiskEncryption(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return &fleet.MDMLinuxDiskEncryptionSummary{}, nil
+	}
+
+	// only the hosts with fleetd can escrow their passphrase or be guided to
+	// encrypt their disk. verifying and removing_enforcement are not
+	// applicable to Linux hosts.
+	sqlFmt := `
+SELECT
+	COUNT(IF((%s), 1, NULL)) AS verified,
+	0 AS verifying,
+	COUNT(IF((%s), 1, NULL)) AS action_required,
+	COUNT(IF((%s), 1, NULL)) AS enforcing,
+	COUNT(IF((%s), 1, NULL)) AS failed,
+	0 AS removing_enforcement
+FROM
+	hosts h
+	JOIN host_disks hd ON h.id = hd.host_id
+	LEFT JOIN host_disk_encryption_keys hdek ON h.id = hdek.host_id
+	LEFT JOIN host_linux_disk_encryption_enforcement hle ON h.id = hle.host_id
+WHERE
+	h.platform IN (?) AND
+	h.orbit_node_key IS NOT NULL AND
+	hd.encrypted IS NOT NULL AND
+	%s`
+
+	args := []any{fleet.HostLinuxOSs}
+	teamFilter := "h.team_id IS NULL"
+	if teamID != nil && *teamID > 0 {
+		teamFilter = "h.team_id = ?"
+		args = append(args, *teamID)
+	}
+
+	stmt, args, err := sqlx.In(fmt.Sprintf(
+		sqlFmt,
+		ds.whereLinuxDiskEncryptionStatus(fleet.DiskEncryptionVerified),
+		ds.whereLinuxDiskEncryptionStatus(fleet.DiskEncryptionActionRequired),
+		ds.whereLinuxDiskEncryptionStatus(fleet.DiskEncryptionEnforcing),
+		ds.whereLinuxDiskEncryptionStatus(fleet.DiskEncryptionFailed),
+		teamFilter,
+	), args...)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build linux disk encryption summary query")
+	}
+
+	var res fleet.MDMLinuxDiskEncryptionSummary
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &res, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get linux disk encryption summary")
+	}
+	return &res, nil
+}
@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/optjson"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinuxDiskEncryptionSummary(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	newLinuxHost := func(name string, teamID *uint, encrypted *bool) *fleet.Host {
+		h := test.NewHost(t, ds, name, "", name+"key", name+"-uuid", time.Now(), test.WithPlatform("ubuntu"))
+		_, err := ds.writer(ctx).ExecContext(ctx, `UPDATE hosts SET orbit_node_key = ? WHERE id = ?`, name+"orbitkey", h.ID)
+		require.NoError(t, err)
+		if teamID != nil {
+			require.NoError(t, ds.AddHostsToTeam(ctx, teamID, []uint{h.ID}))
+		}
+		if encrypted != nil {
+			require.NoError(t, ds.SetOrUpdateHostDisksEncryption(ctx, h.ID, *encrypted))
+		}
+		return h
+	}
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	// encrypted with its passphrase escrowed
+	hostVerified := newLinuxHost("verified", nil, ptr.Bool(true))
+	require.NoError(t, ds.SaveLUKSDiskEncryptionKey(ctx, hostVerified.ID, "passphrase", ptr.Uint(1), ""))
+	// encrypted, the passphrase was not escrowed yet
+	newLinuxHost("encrypted", nil, ptr.Bool(true))
+	// encrypted, the passphrase could not be escrowed
+	hostEscrowFailed := newLinuxHost("escrow-failed", nil, ptr.Bool(true))
+	require.NoError(t, ds.SaveLUKSDiskEncryptionKey(ctx, hostEscrowFailed.ID, "", nil, "wrong passphrase"))
+	// not encrypted, orbit did not report yet
+	newLinuxHost("enforcing", nil, ptr.Bool(false))
+	// not encrypted, the user was guided to encrypt
+	hostGuided := newLinuxHost("guided", nil, ptr.Bool(false))
+	require.NoError(t, ds.SetHostLinuxDiskEncryptionEnforcement(ctx, hostGuided.ID, fleet.LinuxDiskEncryptionEnforcementActionRequired, ""))
+	// not encrypted, the user could not be guided
+	hostGuideFailed := newLinuxHost("guide-failed", nil, ptr.Bool(false))
+	require.NoError(t, ds.SetHostLinuxDiskEncryptionEnforcement(ctx, hostGuideFailed.ID, fleet.LinuxDiskEncryptionEnforcementFailed, "no graphical session"))
+	// the encryption state is unknown
+	newLinuxHost("unknown", nil, nil)
+	// not a linux host
+	macHost := test.NewHost(t, ds, "mac", "", "mackey", "mac-uuid", time.Now())
+	require.NoError(t, ds.SetOrUpdateHostDisksEncryption(ctx, macHost.ID, false))
+	// in another team
+	newLinuxHost("team", &team.ID, ptr.Bool(false))
+
+	// disk encryption is not enabled
+	summary, err := ds.GetMDMLinuxDiskEncryptionSummary(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMLinuxDiskEncryptionSummary{}, *summary)
+
+	ac, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	ac.MDM.EnableDiskEncryption = optjson.SetBool(true)
+	require.NoError(t, ds.SaveAppConfig(ctx, ac))
+
+	summary, err = ds.GetMDMLinuxDiskEncryptionSummary(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMLinuxDiskEncryptionSummary{
+		Verified:       1,
+		ActionRequired: 2,
+		Enforcing:      1,
+		Failed:         2,
+	}, *summary)
+
+	// the encryption of the host reported by orbit takes precedence over the
+	// enforcement status
+	require.NoError(t, ds.SetOrUpdateHostDisksEncryption(ctx, hostGuided.ID, true))
+	require.NoError(t, ds.SaveLUKSDiskEncryptionKey(ctx, hostGuided.ID, "passphrase", ptr.Uint(1), ""))
+	summary, err = ds.GetMDMLinuxDiskEncryptionSummary(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMLinuxDiskEncryptionSummary{
+		Verified:       2,
+		ActionRequired: 1,
+		Enforcing:      1,
+		Failed:         2,
+	}, *summary)
+
+	// the team does not enforce disk encryption
+	summary, err = ds.GetMDMLinuxDiskEncryptionSummary(ctx, &team.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMLinuxDiskEncryptionSummary{}, *summary)
+
+	team.Config.MDM.EnableDiskEncryption = true
+	_, err = ds.SaveTeam(ctx, team)
+	require.NoError(t, err)
+	summary, err = ds.GetMDMLinuxDiskEncryptionSummary(ctx, &team.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMLinuxDiskEncryptionSummary{Enforcing: 1}, *summary)
+
+	// the status is updated on subsequent reports
+	require.NoError(t, ds.SetHostLinuxDiskEncryptionEnforcement(ctx, hostGuideFailed.ID, fleet.LinuxDiskEncryptionEnforcementActionRequired, ""))
+	var detail string
+	require.NoError(t, ds.writer(ctx).GetContext(ctx, &detail, `SELECT detail FROM host_linux_disk_encryption_enforcement WHERE host_id = ?`, hostGuideFailed.ID))
+	require.Empty(t, detail)
+}
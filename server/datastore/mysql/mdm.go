@@ -82,8 +82,9 @@ func (ds *Datastore) ListMDMCommands(
 	tmFilter fleet.TeamFilter,
 	listOpts *fleet.MDMCommandListOptions,
 ) ([]*fleet.MDMCommand, error) {
-	jointStmt := getCombinedMDMCommandsQuery() + ds.whereFilterHostsByTeams(tmFilter, "h")
-	jointStmt, params := appendListOptionsWithCursorToSQL(jointStmt, nil, &listOpts.ListOptions)
+	filterStmt, params := whereFilterMDMCommands(listOpts)
+	jointStmt := getCombinedMDMCommandsQuery() + ds.whereFilterHostsByTeams(tmFilter, "combined_commands") + filterStmt
+	jointStmt, params = appendListOptionsWithCursorToSQL(jointStmt, params, &listOpts.ListOptions)
 	var results []*fleet.MDMCommand
 	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &results, jointStmt, params...); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "list commands")
@@ -91,6 +92,51 @@ func (ds *Datastore) ListMDMCommands(
 	return results, nil
 }
 
+// whereFilterMDMCommands returns the conditions (each prefixed with AND) and
+// their arguments to apply the filters of listOpts to the combined MDM
+// commands query.
+func whereFilterMDMCommands(listOpts *fleet.MDMCommandListOptions) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	switch listOpts.Status {
+	case fleet.MDMCommandStatusFilterPending:
+		conds = append(conds, "status IN ('Pending', 'NotNow')")
+	case fleet.MDMCommandStatusFilterAcknowledged:
+		// Windows reports the SyncML status code, 2xx codes are successes
+		conds = append(conds, "(status = 'Acknowledged' OR status LIKE '2%')")
+	case fleet.MDMCommandStatusFilterError:
+		conds = append(conds, "(status IN ('Error', 'CommandFormatError') OR status LIKE '4%' OR status LIKE '5%')")
+	}
+	if listOpts.RequestType != "" {
+		conds = append(conds, "request_type = ?")
+		args = append(args, listOpts.RequestType)
+	}
+	if listOpts.TeamID != nil {
+		if *listOpts.TeamID == 0 {
+			conds = append(conds, "team_id IS NULL")
+		} else {
+			conds = append(conds, "team_id = ?")
+			args = append(args, *listOpts.TeamID)
+		}
+	}
+	if listOpts.UpdatedAfter != nil {
+		conds = append(conds, "updated_at >= ?")
+		args = append(args, *listOpts.UpdatedAfter)
+	}
+	if listOpts.UpdatedBefore != nil {
+		conds = append(conds, "updated_at < ?")
+		args = append(args, *listOpts.UpdatedBefore)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " AND " + strings.Join(conds, " AND "), args
+}
+
 func (ds *Datastore) getMDMCommand(ctx context.Context, q sqlx.QueryerContext, cmdUUID string) (*fleet.MDMCommand, error) {
 	stmt := getCombinedMDMCommandsQuery() + "command_uuid = ?"
 
@@ -135,7 +181,9 @@ SELECT
 	identifier,
 	checksum,
 	created_at,
-	uploaded_at
+	uploaded_at,
+	shared_with_all_teams,
+	opted_out
 FROM (
 	SELECT
 		profile_uuid,
@@ -145,11 +193,13 @@ FROM (
 		identifier,
 		checksum,
 		created_at,
-		uploaded_at
+		uploaded_at,
+		shared_with_all_teams,
+		%[1]s AS opted_out
 	FROM
-		mdm_apple_configuration_profiles
+		mdm_apple_configuration_profiles p
 	WHERE
-		team_id = ? AND
+		(team_id = ?%[2]s) AND
 		identifier NOT IN (?)
 
 	UNION
@@ -162,11 +212,13 @@ FROM (
 		'' as identifier,
 		'' as checksum,
 		created_at,
-		uploaded_at
+		uploaded_at,
+		shared_with_all_teams,
+		%[3]s AS opted_out
 	FROM
-		mdm_windows_configuration_profiles
+		mdm_windows_configuration_profiles p
 	WHERE
-		team_id = ? AND
+		(team_id = ?%[2]s) AND
 		name NOT IN (?)
 
 	UNION
@@ -179,7 +231,9 @@ FROM (
 		identifier,
 		checksum AS checksum,
 		created_at,
-		uploaded_at
+		uploaded_at,
+		0 AS shared_with_all_teams,
+		NULL AS opted_out
 	FROM mdm_apple_declarations
 	WHERE team_id = ? AND
 		name NOT IN (?)
@@ -191,6 +245,18 @@ FROM (
 		globalOrTeamID = *teamID
 	}
 
+	// the profiles of a team include the profiles shared with all teams, with
+	// whether the team opted out of them.
+	appleOptedOut, windowsOptedOut, sharedCond := "NULL", "NULL", ""
+	if globalOrTeamID > 0 {
+		optedOut := `CASE WHEN p.team_id = 0 THEN
+			EXISTS (SELECT 1 FROM team_shared_library_opt_outs tslo WHERE tslo.team_id = %d AND tslo.%s = p.profile_uuid)
+		END`
+		appleOptedOut = fmt.Sprintf(optedOut, globalOrTeamID, "apple_profile_uuid")
+		windowsOptedOut = fmt.Sprintf(optedOut, globalOrTeamID, "windows_profile_uuid")
+		sharedCond = " OR (team_id = 0 AND shared_with_all_teams = 1)"
+	}
+
 	fleetIdentsMap := mobileconfig.FleetPayloadIdentifiers()
 	fleetIdentifiers := make([]string, 0, len(fleetIdentsMap))
 	for k := range fleetIdentsMap {
@@ -203,7 +269,7 @@ FROM (
 	}
 
 	args := []any{globalOrTeamID, fleetIdentifiers, globalOrTeamID, fleetNames, globalOrTeamID, fleetNames}
-	stmt, args := appendListOptionsWithCursorToSQL(selectStmt, args, &opt)
+	stmt, args := appendListOptionsWithCursorToSQL(fmt.Sprintf(selectStmt, appleOptedOut, sharedCond, windowsOptedOut), args, &opt)
 
 	stmt, args, err := sqlx.In(stmt, args...)
 	if err != nil {
@@ -249,13 +315,87 @@ FROM (
 	}
 	for _, label := range labels {
 		if prof, ok := profMap[label.ProfileUUID]; ok {
-			prof.Labels = append(prof.Labels, label)
+			prof.AddLabel(label)
 		}
 	}
 
 	return profs, metaData, nil
 }
 
+func (ds *Datastore) PreviewMDMProfileTargets(ctx context.Context, teamID *uint, platform string, labels []fleet.ConfigurationProfileLabel) (*fleet.MDMProfileTargetsPreview, error) {
+	var platformFilter string
+	switch platform {
+	case "darwin":
+		platformFilter = `h.platform IN ('darwin', 'ios', 'ipados') AND EXISTS (
+			SELECT 1 FROM nano_enrollments ne
+			WHERE ne.device_id = h.uuid AND ne.enabled = 1 AND ne.type = 'Device'
+		)`
+	case "windows":
+		platformFilter = `h.platform = 'windows' AND EXISTS (
+			SELECT 1 FROM mdm_windows_enrollments mwe WHERE mwe.host_uuid = h.uuid
+		)`
+	default:
+		return nil, ctxerr.Errorf(ctx, "unsupported platform %s", platform)
+	}
+
+	var args []any
+	var labelsJoin, having string
+	if len(labels) > 0 {
+		// the labels are not saved yet, so they are provided as a derived
+		// table with the same columns as mdm_configuration_profile_labels.
+		var labelsTable strings.Builder
+		for i, l := range labels {
+			if i > 0 {
+				labelsTable.WriteString(" UNION ALL ")
+			}
+			labelsTable.WriteString("SELECT ? AS label_id, ? AS exclude, ? AS require_all")
+			args = append(args, l.LabelID, l.Exclude, l.RequireAll)
+		}
+		labelsJoin = fmt.Sprintf(`
+		JOIN ( %s ) mcpl
+		LEFT OUTER JOIN label_membership lm
+			ON lm.label_id = mcpl.label_id AND lm.host_id = h.id
+		LEFT OUTER JOIN labels lbl
+			ON lbl.id = mcpl.label_id`, labelsTable.String())
+		having = `HAVING ` + profileLabelsTargetingCondition("mcpl", "h")
+	}
+
+	teamFilter := "h.team_id IS NULL"
+	if teamID != nil && *teamID > 0 {
+		teamFilter = "h.team_id = ?"
+		args = append(args, *teamID)
+	}
+
+	targetsStmt := fmt.Sprintf(`
+	SELECT
+		h.id,
+		COALESCE(hdn.display_name, '') AS display_name
+	FROM
+		hosts h
+		LEFT OUTER JOIN host_display_names hdn
+			ON hdn.host_id = h.id
+		%s
+	WHERE
+		%s AND
+		%s
+	GROUP BY
+		h.id, h.label_updated_at, hdn.display_name
+	%s`, labelsJoin, teamFilter, platformFilter, having)
+
+	var res fleet.MDMProfileTargetsPreview
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &res.HostsCount,
+		`SELECT COUNT(*) FROM (`+targetsStmt+`) targets`, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "count profile targets")
+	}
+
+	res.Hosts = []fleet.MDMProfileTargetHost{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &res.Hosts,
+		targetsStmt+fmt.Sprintf(` ORDER BY h.id LIMIT %d`, fleet.MaxMDMProfileTargetsPreviewHosts), args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list profile targets")
+	}
+	return &res, nil
+}
+
 func (ds *Datastore) listProfileLabelsForProfiles(ctx context.Context, winProfUUIDs, macProfUUIDs, macDeclUUIDs []string) ([]fleet.ConfigurationProfileLabel, error) {
 	// load the labels associated with those profiles
 	const labelsStmt = `
@@ -263,7 +403,9 @@ SELECT
 	COALESCE(apple_profile_uuid, windows_profile_uuid) as profile_uuid,
 	label_name,
 	COALESCE(label_id, 0) as label_id,
-	IF(label_id IS NULL, 1, 0) as broken
+	IF(label_id IS NULL, 1, 0) as broken,
+	exclude,
+	require_all
 FROM
 	mdm_configuration_profile_labels mcpl
 WHERE
@@ -274,7 +416,9 @@ SELECT
 	apple_declaration_uuid as profile_uuid,
 	label_name,
 	COALESCE(label_id, 0) as label_id,
-	IF(label_id IS NULL, 1, 0) as broken
+	IF(label_id IS NULL, 1, 0) as broken,
+	exclude,
+	require_all
 FROM
 	mdm_declaration_labels mdl
 WHERE
@@ -423,9 +567,9 @@ func (ds *Datastore) bulkSetPendingMDMHostProfilesDB(
 SELECT DISTINCT h.uuid, h.platform
 FROM hosts h
 JOIN mdm_apple_configuration_profiles macp
-	ON h.team_id = macp.team_id OR (h.team_id IS NULL AND macp.team_id = 0)
+	ON ` + profileTargetsHostTeamCondition("darwin", "macp", "h") + `
 WHERE
-	macp.profile_uuid IN (?) AND h.platform = 'darwin'`
+	macp.profile_uuid IN (?) AND h.platform IN ('darwin', 'ios', 'ipados')`
 		args = append(args, macProfUUIDs)
 
 	case len(winProfUUIDs) > 0:
@@ -435,7 +579,7 @@ WHERE
 SELECT DISTINCT h.uuid, h.platform
 FROM hosts h
 JOIN mdm_windows_configuration_profiles mawp
-	ON h.team_id = mawp.team_id OR (h.team_id IS NULL AND mawp.team_id = 0)
+	ON ` + profileTargetsHostTeamCondition("windows", "mawp", "h") + `
 WHERE
 	mawp.profile_uuid IN (?) AND h.platform = 'windows'`
 		args = append(args, winProfUUIDs)
@@ -458,7 +602,7 @@ WHERE
 	var winHosts []string
 	for _, h := range hosts {
 		switch h.Platform {
-		case "darwin":
+		case "darwin", "ios", "ipados":
 			macHosts = append(macHosts, h.UUID)
 		case "windows":
 			winHosts = append(winHosts, h.UUID)
@@ -686,8 +830,9 @@ SELECT
 	0 AS count_host_labels
 FROM
 	mdm_windows_configuration_profiles mwcp
+	JOIN hosts h ON h.id = ?
 WHERE
-	mwcp.team_id = ?
+	(mwcp.team_id = ? OR ` + sharedProfileTargetsHostCondition("windows", "mwcp", "h") + `)
 	AND NOT EXISTS (
 		SELECT
 			1
@@ -708,19 +853,19 @@ GROUP BY  name, syncml
 		JOIN mdm_configuration_profile_labels mcpl ON mcpl.windows_profile_uuid = mwcp.profile_uuid
 		LEFT OUTER JOIN label_membership lm ON lm.label_id = mcpl.label_id
 			AND lm.host_id = ?
+		JOIN hosts h ON h.id = ?
 	WHERE
-		mwcp.team_id = ?
+		(mwcp.team_id = ? OR ` + sharedProfileTargetsHostCondition("windows", "mwcp", "h") + `)
 	GROUP BY
 		name, syncml
 	HAVING
-		count_profile_labels > 0
-		AND count_host_labels = count_profile_labels
+		` + profileLabelsTargetingCondition("mcpl", "") + `
 
   `
 
 	var profiles []*fleet.ExpectedMDMProfile
-	// Note: teamID provided twice
-	err := sqlx.SelectContext(ctx, ds.reader(ctx), &profiles, stmt, teamID, hostID, teamID)
+	// Note: hostID and teamID provided twice
+	err := sqlx.SelectContext(ctx, ds.reader(ctx), &profiles, stmt, hostID, teamID, hostID, hostID, teamID)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "running query for windows profiles")
 	}
@@ -750,8 +895,9 @@ FROM
 			mdm_apple_configuration_profiles
 		GROUP BY
 			checksum) cs ON macp.checksum = cs.checksum
+	JOIN hosts h ON h.id = ?
 WHERE
-	macp.team_id = ?
+	(macp.team_id = ? OR ` + sharedProfileTargetsHostCondition("darwin", "macp", "h") + `)
 	AND NOT EXISTS (
 		SELECT
 			1
@@ -760,7 +906,7 @@ WHERE
 		WHERE
 			mcpl.apple_profile_uuid = macp.profile_uuid)
 	UNION
-	-- label-based profiles where the host is a member of all the labels
+	-- label-based profiles that target the host
 	SELECT
 		macp.identifier AS identifier,
 		COUNT(*) AS count_profile_labels,
@@ -779,18 +925,18 @@ WHERE
 		JOIN mdm_configuration_profile_labels mcpl ON mcpl.apple_profile_uuid = macp.profile_uuid
 		LEFT OUTER JOIN label_membership lm ON lm.label_id = mcpl.label_id
 			AND lm.host_id = ?
+		JOIN hosts h ON h.id = ?
 	WHERE
-		macp.team_id = ?
+		(macp.team_id = ? OR ` + sharedProfileTargetsHostCondition("darwin", "macp", "h") + `)
 	GROUP BY
 		identifier
 	HAVING
-		count_profile_labels > 0
-		AND count_host_labels = count_profile_labels
+		` + profileLabelsTargetingCondition("mcpl", "") + `
 	`
 
 	var rows []*fleet.ExpectedMDMProfile
-	// Note: teamID provided twice
-	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, teamID, hostID, teamID); err != nil {
+	// Note: hostID and teamID provided twice
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, hostID, teamID, hostID, hostID, teamID); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, fmt.Sprintf("getting expected profiles for host in team %d", teamID))
 	}
 
@@ -879,6 +1025,36 @@ WHERE
 	return dest, nil
 }
 
+// profileLabelsTargetingCondition returns the HAVING condition that matches
+// the hosts targeted by the labels of label-based profiles. The query must
+// join the labels of the profile as labelsAlias and the label membership of
+// the host as lm, and group the rows by profile and host. A profile with a
+// broken label is never matched.
+//
+// If hostAlias is set, the query must also join the labels as lbl and the
+// hosts as hostAlias, and a dynamic label that the host must not be a member
+// of only matches once the labels of the host were updated after the label
+// was created, so that the profile is not delivered before the membership of
+// a new host is known.
+func profileLabelsTargetingCondition(labelsAlias, hostAlias string) string {
+	cond := fmt.Sprintf(`
+		SUM(%[1]s.label_id IS NULL) = 0 AND
+		-- member of all the "include all" labels
+		SUM(%[1]s.exclude = 0 AND %[1]s.require_all = 1) =
+			SUM(%[1]s.exclude = 0 AND %[1]s.require_all = 1 AND lm.label_id IS NOT NULL) AND
+		-- member of at least one of the "include any" labels, if any
+		( SUM(%[1]s.exclude = 0 AND %[1]s.require_all = 0) = 0 OR
+			SUM(%[1]s.exclude = 0 AND %[1]s.require_all = 0 AND lm.label_id IS NOT NULL) > 0 ) AND
+		-- member of none of the "exclude any" labels
+		SUM(%[1]s.exclude = 1 AND lm.label_id IS NOT NULL) = 0`, labelsAlias)
+	if hostAlias != "" {
+		cond += fmt.Sprintf(` AND
+		SUM(%[1]s.exclude = 1 AND lbl.label_membership_type = %[3]d AND %[2]s.label_updated_at < lbl.created_at) = 0`,
+			labelsAlias, hostAlias, fleet.LabelMembershipTypeDynamic)
+	}
+	return cond
+}
+
 func batchSetProfileLabelAssociationsDB(
 	ctx context.Context,
 	tx sqlx.ExtContext,
@@ -915,11 +1091,13 @@ func batchSetProfileLabelAssociationsDB(
 
 	upsertStmt := `
 	  INSERT INTO mdm_configuration_profile_labels
-              (%s_profile_uuid, label_id, label_name)
+              (%s_profile_uuid, label_id, label_name, exclude, require_all)
           VALUES
               %s
           ON DUPLICATE KEY UPDATE
-              label_id = VALUES(label_id)
+              label_id = VALUES(label_id),
+              exclude = VALUES(exclude),
+              require_all = VALUES(require_all)
 	`
 
 	var (
@@ -935,9 +1113,9 @@ func batchSetProfileLabelAssociationsDB(
 			insertBuilder.WriteString(",")
 			deleteBuilder.WriteString(",")
 		}
-		insertBuilder.WriteString("(?, ?, ?)")
+		insertBuilder.WriteString("(?, ?, ?, ?, ?)")
 		deleteBuilder.WriteString("(?, ?)")
-		insertParams = append(insertParams, pl.ProfileUUID, pl.LabelID, pl.LabelName)
+		insertParams = append(insertParams, pl.ProfileUUID, pl.LabelID, pl.LabelName, pl.Exclude, pl.RequireAll)
 		deleteParams = append(deleteParams, pl.ProfileUUID, pl.LabelID)
 
 		setProfileUUIDs[pl.ProfileUUID] = struct{}{}
@@ -1042,11 +1220,13 @@ func (ds *Datastore) GetHostCertAssociationsToExpire(ctx context.Context, expiry
 		`SELECT
 			h.uuid as host_uuid,
 			ncaa.sha256 as sha256,
-			COALESCE(MAX(hm.fleet_enroll_ref), '') as enroll_reference
+			COALESCE(MAX(hm.fleet_enroll_ref), '') as enroll_reference,
+			COALESCE(MAX(ne.topic), '') as topic
 		 FROM
 			nano_cert_auth_associations ncaa
 			LEFT JOIN hosts h ON h.uuid = ncaa.id
 			LEFT JOIN host_mdm hm ON hm.host_id = h.id
+			LEFT JOIN nano_enrollments ne ON ne.id = ncaa.id
 		 WHERE
 			cert_not_valid_after BETWEEN '0000-00-00' AND DATE_ADD(CURDATE(), INTERVAL ? DAY)
 			AND renew_command_uuid IS NULL
@@ -1069,6 +1249,36 @@ func (ds *Datastore) GetHostCertAssociationsToExpire(ctx context.Context, expiry
 	return uuids, nil
 }
 
+func (ds *Datastore) GetHostSCEPIdentityAssociation(ctx context.Context, hostUUID string) (*fleet.SCEPIdentityAssociation, error) {
+	const stmt = `
+		SELECT
+			ncaa.id as host_uuid,
+			ncaa.sha256 as sha256,
+			COALESCE((
+				SELECT MAX(hm.fleet_enroll_ref)
+				FROM hosts h
+				JOIN host_mdm hm ON hm.host_id = h.id
+				WHERE h.uuid = ncaa.id
+			), '') as enroll_reference,
+			COALESCE(ncaa.renew_command_uuid, '') as renew_command_uuid,
+			COALESCE((SELECT ne.topic FROM nano_enrollments ne WHERE ne.id = ncaa.id), '') as topic
+		FROM
+			nano_cert_auth_associations ncaa
+		WHERE
+			ncaa.id = ?
+		ORDER BY ncaa.cert_not_valid_after DESC, ncaa.created_at DESC
+		LIMIT 1`
+
+	var assoc fleet.SCEPIdentityAssociation
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &assoc, stmt, hostUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("SCEPIdentityAssociation").WithName(hostUUID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host SCEP identity association")
+	}
+	return &assoc, nil
+}
+
 func (ds *Datastore) SetCommandForPendingSCEPRenewal(ctx context.Context, assocs []fleet.SCEPIdentityAssociation, cmdUUID string) error {
 	if len(assocs) == 0 {
 		return nil
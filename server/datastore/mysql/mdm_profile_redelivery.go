@@ -0,0 +1,234 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const selectMDMProfileRedeliveryCampaignStmt = `
+	SELECT
+		id,
+		profile_uuid,
+		profile_name,
+		team_id,
+		hosts_per_minute,
+		status,
+		total_hosts,
+		delivered_hosts,
+		last_batch_at,
+		created_by_id,
+		created_at,
+		updated_at
+	FROM mdm_profile_redelivery_campaigns`
+
+func (ds *Datastore) NewMDMProfileRedeliveryCampaign(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	table, column, err := getTableAndColumnNameForHostMDMProfileUUID(campaign.ProfileUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "getting table and column")
+	}
+
+	var campaignID uint
+	err = ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// only one campaign can be in progress for a profile
+		var inProgress bool
+		if err := sqlx.GetContext(ctx, tx, &inProgress, `
+			SELECT EXISTS (
+				SELECT 1 FROM mdm_profile_redelivery_campaigns
+				WHERE profile_uuid = ? AND status IN (?, ?)
+			)`, campaign.ProfileUUID, fleet.MDMProfileRedeliveryCampaignRunning, fleet.MDMProfileRedeliveryCampaignPaused,
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "check campaign in progress")
+		}
+		if inProgress {
+			return ctxerr.Wrap(ctx, alreadyExists("MDMProfileRedeliveryCampaign", campaign.ProfileUUID))
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO mdm_profile_redelivery_campaigns
+				(profile_uuid, profile_name, team_id, hosts_per_minute, status, created_by_id)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			campaign.ProfileUUID, campaign.ProfileName, campaign.TeamID, campaign.HostsPerMinute,
+			fleet.MDMProfileRedeliveryCampaignRunning, campaign.CreatedByID,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert campaign")
+		}
+		id, _ := res.LastInsertId()
+		campaignID = uint(id)
+
+		// the campaign targets the hosts that have the profile installed, the
+		// hosts for which the profile is pending already get it delivered.
+		res, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO mdm_profile_redelivery_campaign_hosts (campaign_id, host_uuid)
+			SELECT ?, host_uuid FROM %s
+			WHERE %s = ? AND operation_type = ? AND status IS NOT NULL AND status != ?`, table, column),
+			campaignID, campaign.ProfileUUID, fleet.MDMOperationTypeInstall, fleet.MDMDeliveryPending,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert campaign hosts")
+		}
+		total, _ := res.RowsAffected()
+
+		status := fleet.MDMProfileRedeliveryCampaignRunning
+		if total == 0 {
+			status = fleet.MDMProfileRedeliveryCampaignCompleted
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE mdm_profile_redelivery_campaigns SET total_hosts = ?, status = ? WHERE id = ?`,
+			total, status, campaignID); err != nil {
+			return ctxerr.Wrap(ctx, err, "update campaign total hosts")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create profile redelivery campaign")
+	}
+	return ds.mdmProfileRedeliveryCampaignDB(ctx, ds.writer(ctx), campaignID)
+}
+
+func (ds *Datastore) MDMProfileRedeliveryCampaign(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	return ds.mdmProfileRedeliveryCampaignDB(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) mdmProfileRedeliveryCampaignDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	var campaign fleet.MDMProfileRedeliveryCampaign
+	if err := sqlx.GetContext(ctx, q, &campaign, selectMDMProfileRedeliveryCampaignStmt+` WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("MDMProfileRedeliveryCampaign").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get profile redelivery campaign")
+	}
+	return &campaign, nil
+}
+
+func (ds *Datastore) ListMDMProfileRedeliveryCampaigns(ctx context.Context, opts fleet.MDMProfileRedeliveryCampaignListOptions) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+	stmt := selectMDMProfileRedeliveryCampaignStmt
+	var args []any
+	if opts.TeamID != nil {
+		stmt += ` WHERE team_id = ?`
+		args = append(args, *opts.TeamID)
+	} else {
+		stmt += ` WHERE team_id IS NULL`
+	}
+	if opts.Status != "" {
+		stmt += ` AND status = ?`
+		args = append(args, opts.Status)
+	}
+	stmt += ` ORDER BY id DESC`
+
+	campaigns := []*fleet.MDMProfileRedeliveryCampaign{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &campaigns, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list profile redelivery campaigns")
+	}
+	return campaigns, nil
+}
+
+func (ds *Datastore) ListRunningMDMProfileRedeliveryCampaigns(ctx context.Context) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+	campaigns := []*fleet.MDMProfileRedeliveryCampaign{}
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &campaigns,
+		selectMDMProfileRedeliveryCampaignStmt+` WHERE status = ? ORDER BY id`, fleet.MDMProfileRedeliveryCampaignRunning); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list running profile redelivery campaigns")
+	}
+	return campaigns, nil
+}
+
+func (ds *Datastore) UpdateMDMProfileRedeliveryCampaign(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+		UPDATE mdm_profile_redelivery_campaigns
+		SET status = ?, hosts_per_minute = ?, last_batch_at = ?
+		WHERE id = ?`,
+		campaign.Status, campaign.HostsPerMinute, campaign.LastBatchAt, campaign.ID,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update profile redelivery campaign")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// RowsAffected is 0 if nothing changed too, check that the campaign exists
+		if _, err := ds.mdmProfileRedeliveryCampaignDB(ctx, ds.writer(ctx), campaign.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) RedeliverMDMProfileCampaignBatch(ctx context.Context, campaignID uint, batchSize uint, now time.Time) (uint, error) {
+	var delivered uint
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		delivered = 0
+
+		var campaign fleet.MDMProfileRedeliveryCampaign
+		if err := sqlx.GetContext(ctx, tx, &campaign, selectMDMProfileRedeliveryCampaignStmt+` WHERE id = ? FOR UPDATE`, campaignID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ctxerr.Wrap(ctx, notFound("MDMProfileRedeliveryCampaign").WithID(campaignID))
+			}
+			return ctxerr.Wrap(ctx, err, "get campaign")
+		}
+		if campaign.Status != fleet.MDMProfileRedeliveryCampaignRunning {
+			// paused in the meantime, nothing to do
+			return nil
+		}
+
+		table, column, err := getTableAndColumnNameForHostMDMProfileUUID(campaign.ProfileUUID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "getting table and column")
+		}
+
+		var hostUUIDs []string
+		if err := sqlx.SelectContext(ctx, tx, &hostUUIDs, `
+			SELECT host_uuid FROM mdm_profile_redelivery_campaign_hosts
+			WHERE campaign_id = ? AND delivered_at IS NULL
+			ORDER BY host_uuid
+			LIMIT ?`, campaignID, batchSize); err != nil {
+			return ctxerr.Wrap(ctx, err, "select campaign hosts batch")
+		}
+
+		if len(hostUUIDs) > 0 {
+			// update the status to NULL to trigger resending on the next cron
+			// run, as done by ResendHostMDMProfile. Hosts that don't have the
+			// profile anymore are not affected.
+			stmt, args, err := sqlx.In(fmt.Sprintf(`
+				UPDATE %s SET status = NULL
+				WHERE %s = ? AND operation_type = ? AND host_uuid IN (?)`, table, column),
+				campaign.ProfileUUID, fleet.MDMOperationTypeInstall, hostUUIDs)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "build redeliver profile statement")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "redeliver profile to hosts")
+			}
+
+			stmt, args, err = sqlx.In(`
+				UPDATE mdm_profile_redelivery_campaign_hosts SET delivered_at = ?
+				WHERE campaign_id = ? AND host_uuid IN (?)`, now, campaignID, hostUUIDs)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "build mark campaign hosts statement")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "mark campaign hosts delivered")
+			}
+			delivered = uint(len(hostUUIDs))
+		}
+
+		status := fleet.MDMProfileRedeliveryCampaignRunning
+		if len(hostUUIDs) < int(batchSize) || campaign.DeliveredHosts+delivered >= campaign.TotalHosts {
+			status = fleet.MDMProfileRedeliveryCampaignCompleted
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE mdm_profile_redelivery_campaigns
+			SET delivered_hosts = delivered_hosts + ?, last_batch_at = ?, status = ?
+			WHERE id = ?`, delivered, now, status, campaignID); err != nil {
+			return ctxerr.Wrap(ctx, err, "update campaign progress")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "redeliver profile campaign batch")
+	}
+	return delivered, nil
+}
@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRedeliveryCampaigns(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CreateAndList", testMDMProfileRedeliveryCampaignsCreateAndList},
+		{"RedeliverBatches", testMDMProfileRedeliveryCampaignsRedeliverBatches},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testMDMProfileRedeliveryCampaignsCreateAndList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	prof, err := ds.NewMDMAppleConfigProfile(ctx, *configProfileForTest(t, "p1", "p1", "p1"))
+	require.NoError(t, err)
+
+	hosts := make([]*fleet.Host, 4)
+	for i := range hosts {
+		hosts[i] = &fleet.Host{UUID: fmt.Sprintf("h%d", i)}
+	}
+	// hosts 0 and 1 have the profile verified, host 2 failed and host 3 is
+	// still pending, so it is not targeted.
+	upsertHostCPs(hosts[:2], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryVerified, ctx, ds, t)
+	upsertHostCPs(hosts[2:3], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryFailed, ctx, ds, t)
+	upsertHostCPs(hosts[3:], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryPending, ctx, ds, t)
+
+	campaign, err := ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    prof.ProfileUUID,
+		ProfileName:    prof.Name,
+		HostsPerMinute: 100,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, campaign.ID)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignRunning, campaign.Status)
+	require.EqualValues(t, 3, campaign.TotalHosts)
+	require.Zero(t, campaign.DeliveredHosts)
+	require.Nil(t, campaign.TeamID)
+	require.Nil(t, campaign.LastBatchAt)
+
+	// only one campaign in progress per profile
+	_, err = ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    prof.ProfileUUID,
+		ProfileName:    prof.Name,
+		HostsPerMinute: 10,
+	})
+	require.Error(t, err)
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	// a campaign for a profile without hosts is immediately completed
+	prof2, err := ds.NewMDMAppleConfigProfile(ctx, *configProfileForTest(t, "p2", "p2", "p2"))
+	require.NoError(t, err)
+	campaign2, err := ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    prof2.ProfileUUID,
+		ProfileName:    prof2.Name,
+		HostsPerMinute: 100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignCompleted, campaign2.Status)
+	require.Zero(t, campaign2.TotalHosts)
+
+	got, err := ds.MDMProfileRedeliveryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.Equal(t, campaign, got)
+
+	_, err = ds.MDMProfileRedeliveryCampaign(ctx, campaign2.ID+100)
+	require.True(t, fleet.IsNotFound(err))
+
+	list, err := ds.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, campaign2.ID, list[0].ID)
+	require.Equal(t, campaign.ID, list[1].ID)
+
+	list, err = ds.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{Status: fleet.MDMProfileRedeliveryCampaignRunning})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, campaign.ID, list[0].ID)
+
+	list, err = ds.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{TeamID: ptr.Uint(1)})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// pause the campaign and change its rate
+	campaign.Status = fleet.MDMProfileRedeliveryCampaignPaused
+	campaign.HostsPerMinute = 5
+	require.NoError(t, ds.UpdateMDMProfileRedeliveryCampaign(ctx, campaign))
+	got, err = ds.MDMProfileRedeliveryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignPaused, got.Status)
+	require.EqualValues(t, 5, got.HostsPerMinute)
+
+	// no change is not an error
+	require.NoError(t, ds.UpdateMDMProfileRedeliveryCampaign(ctx, campaign))
+	err = ds.UpdateMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{ID: campaign2.ID + 100, Status: fleet.MDMProfileRedeliveryCampaignPaused})
+	require.True(t, fleet.IsNotFound(err))
+
+	running, err := ds.ListRunningMDMProfileRedeliveryCampaigns(ctx)
+	require.NoError(t, err)
+	require.Empty(t, running)
+}
+
+func testMDMProfileRedeliveryCampaignsRedeliverBatches(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	prof, err := ds.NewMDMAppleConfigProfile(ctx, *configProfileForTest(t, "p1", "p1", "p1"))
+	require.NoError(t, err)
+
+	hosts := make([]*fleet.Host, 5)
+	for i := range hosts {
+		hosts[i] = &fleet.Host{UUID: fmt.Sprintf("h%d", i)}
+	}
+	upsertHostCPs(hosts, []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryVerified, ctx, ds, t)
+
+	campaign, err := ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    prof.ProfileUUID,
+		ProfileName:    prof.Name,
+		HostsPerMinute: 2,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, campaign.TotalHosts)
+
+	pendingHosts := func() []string {
+		var uuids []string
+		err := sqlx.SelectContext(ctx, ds.reader(ctx), &uuids,
+			`SELECT host_uuid FROM host_mdm_apple_profiles WHERE profile_uuid = ? AND status IS NULL ORDER BY host_uuid`, prof.ProfileUUID)
+		require.NoError(t, err)
+		return uuids
+	}
+
+	n, err := ds.RedeliverMDMProfileCampaignBatch(ctx, campaign.ID, 2, now)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.Equal(t, []string{"h0", "h1"}, pendingHosts())
+
+	got, err := ds.MDMProfileRedeliveryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, got.DeliveredHosts)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignRunning, got.Status)
+	require.NotNil(t, got.LastBatchAt)
+	require.Equal(t, now, got.LastBatchAt.UTC())
+
+	// a paused campaign is not processed
+	got.Status = fleet.MDMProfileRedeliveryCampaignPaused
+	require.NoError(t, ds.UpdateMDMProfileRedeliveryCampaign(ctx, got))
+	n, err = ds.RedeliverMDMProfileCampaignBatch(ctx, campaign.ID, 2, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Equal(t, []string{"h0", "h1"}, pendingHosts())
+
+	got.Status = fleet.MDMProfileRedeliveryCampaignRunning
+	got.LastBatchAt = nil
+	require.NoError(t, ds.UpdateMDMProfileRedeliveryCampaign(ctx, got))
+
+	// the profile is removed from h3, it is processed but not redelivered
+	_, err = ds.writer(ctx).ExecContext(ctx, `DELETE FROM host_mdm_apple_profiles WHERE host_uuid = ?`, "h3")
+	require.NoError(t, err)
+
+	n, err = ds.RedeliverMDMProfileCampaignBatch(ctx, campaign.ID, 2, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.Equal(t, []string{"h0", "h1", "h2"}, pendingHosts())
+
+	// the last batch completes the campaign
+	n, err = ds.RedeliverMDMProfileCampaignBatch(ctx, campaign.ID, 2, now.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	require.Equal(t, []string{"h0", "h1", "h2", "h4"}, pendingHosts())
+
+	got, err = ds.MDMProfileRedeliveryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, got.DeliveredHosts)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignCompleted, got.Status)
+
+	// a new campaign can be created once the previous one is completed
+	upsertHostCPs(hosts[:1], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryVerified, ctx, ds, t)
+	campaign2, err := ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    prof.ProfileUUID,
+		ProfileName:    prof.Name,
+		HostsPerMinute: 2,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, campaign2.TotalHosts)
+
+	_, err = ds.RedeliverMDMProfileCampaignBatch(ctx, campaign2.ID+100, 2, now)
+	require.True(t, fleet.IsNotFound(err))
+}
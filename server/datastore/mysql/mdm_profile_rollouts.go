@@ -0,0 +1,233 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// profileRolloutCanaryCondition returns the SQL condition that is true if the
+// host identified by hostUUIDExpr is part of the canary sample of the rollout
+// aliased as rolloutAlias for the profile identified by profileUUIDExpr. The
+// sample is deterministic so that the same hosts are selected every time the
+// condition is evaluated, and it is independent for each profile.
+//
+// Note that MOD is used instead of the % operator as the condition may be
+// embedded in statements that are formatted with fmt.Sprintf.
+func profileRolloutCanaryCondition(rolloutAlias, profileUUIDExpr, hostUUIDExpr string) string {
+	return fmt.Sprintf(`MOD(CRC32(CONCAT(%s, %s)), 100) < %s.canary_percentage`,
+		profileUUIDExpr, hostUUIDExpr, rolloutAlias)
+}
+
+// profileRolloutGateCondition returns the SQL condition that is true if the
+// profile identified by profileUUIDExpr can be delivered to the host
+// identified by hostUUIDExpr, i.e. if the profile has no rollout, if its
+// rollout is completed, or if the host is part of its canary sample.
+func profileRolloutGateCondition(profileUUIDExpr, hostUUIDExpr string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+			SELECT 1
+			FROM mdm_profile_rollouts mpr
+			WHERE
+				mpr.profile_uuid = %s AND
+				mpr.status != '%s' AND
+				NOT ( %s )
+		)`, profileUUIDExpr, fleet.MDMProfileRolloutCompleted,
+		profileRolloutCanaryCondition("mpr", profileUUIDExpr, hostUUIDExpr))
+}
+
+// mdmProfileRolloutSpec is the rollout to set for a profile while setting its
+// contents.
+type mdmProfileRolloutSpec struct {
+	profileUUID string
+	// settings is nil if the profile has no rollout.
+	settings *fleet.MDMProfileRolloutSettings
+	// restart is true if the profile is new or its contents changed, in which
+	// case the rollout starts over with its canary stage.
+	restart bool
+}
+
+// batchSetMDMProfileRolloutsDB sets the rollouts of the provided profiles,
+// deleting the rollout of those that have none.
+func batchSetMDMProfileRolloutsDB(ctx context.Context, tx sqlx.ExtContext, rollouts []mdmProfileRolloutSpec) error {
+	var toDelete []string
+	for _, r := range rollouts {
+		if r.settings == nil {
+			toDelete = append(toDelete, r.profileUUID)
+			continue
+		}
+		if err := upsertMDMProfileRolloutDB(ctx, tx, r.profileUUID, *r.settings, r.restart); err != nil {
+			return err
+		}
+	}
+
+	if len(toDelete) > 0 {
+		stmt, args, err := sqlx.In(`DELETE FROM mdm_profile_rollouts WHERE profile_uuid IN (?)`, toDelete)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build statement to delete profile rollouts")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete profile rollouts")
+		}
+	}
+	return nil
+}
+
+// upsertMDMProfileRolloutDB creates or updates the rollout settings of a
+// profile. If restart is true, the rollout (re)starts with its canary stage,
+// otherwise only its settings are updated.
+func upsertMDMProfileRolloutDB(ctx context.Context, tx sqlx.ExtContext, profileUUID string,
+	settings fleet.MDMProfileRolloutSettings, restart bool,
+) error {
+	const stmt = `
+INSERT INTO mdm_profile_rollouts
+  (profile_uuid, canary_percentage, failure_threshold_percentage, timeout_minutes, status)
+VALUES
+  (?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  canary_percentage = VALUES(canary_percentage),
+  failure_threshold_percentage = VALUES(failure_threshold_percentage),
+  timeout_minutes = VALUES(timeout_minutes),
+  status = IF(?, VALUES(status), status),
+  reason = IF(?, '', reason),
+  started_at = IF(?, CURRENT_TIMESTAMP, started_at),
+  finished_at = IF(?, NULL, finished_at)
+`
+	if _, err := tx.ExecContext(ctx, stmt, profileUUID, settings.CanaryPercentage,
+		settings.FailureThresholdPercentage, settings.TimeoutMinutes, fleet.MDMProfileRolloutCanary,
+		restart, restart, restart, restart,
+	); err != nil {
+		return ctxerr.Wrapf(ctx, err, "upsert rollout of profile %s", profileUUID)
+	}
+	return nil
+}
+
+const selectMDMProfileRolloutStmt = `
+	SELECT
+		mpr.profile_uuid,
+		COALESCE(macp.name, mwcp.name, '') AS profile_name,
+		NULLIF(COALESCE(macp.team_id, mwcp.team_id, 0), 0) AS team_id,
+		mpr.canary_percentage,
+		mpr.failure_threshold_percentage,
+		mpr.timeout_minutes,
+		mpr.status,
+		mpr.reason,
+		mpr.started_at,
+		mpr.finished_at,
+		mpr.created_at,
+		mpr.updated_at
+	FROM mdm_profile_rollouts mpr
+		LEFT JOIN mdm_apple_configuration_profiles macp
+			ON macp.profile_uuid = mpr.profile_uuid
+		LEFT JOIN mdm_windows_configuration_profiles mwcp
+			ON mwcp.profile_uuid = mpr.profile_uuid`
+
+func (ds *Datastore) GetMDMProfileRollout(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error) {
+	var rollout fleet.MDMProfileRollout
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &rollout, selectMDMProfileRolloutStmt+` WHERE mpr.profile_uuid = ?`, profileUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("MDMProfileRollout").WithName(profileUUID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get profile rollout")
+	}
+
+	res, err := ds.GetMDMProfileRolloutResults(ctx, profileUUID)
+	if err != nil {
+		return nil, err
+	}
+	rollout.Results = *res
+	return &rollout, nil
+}
+
+func (ds *Datastore) ListActiveMDMProfileRollouts(ctx context.Context) ([]*fleet.MDMProfileRollout, error) {
+	var rollouts []*fleet.MDMProfileRollout
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rollouts,
+		selectMDMProfileRolloutStmt+` WHERE mpr.status = ? ORDER BY mpr.started_at, mpr.profile_uuid`,
+		fleet.MDMProfileRolloutCanary,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list active profile rollouts")
+	}
+	return rollouts, nil
+}
+
+func (ds *Datastore) GetMDMProfileRolloutResults(ctx context.Context, profileUUID string) (*fleet.MDMProfileRolloutResults, error) {
+	// the canary hosts are the hosts in the desired state of the profile that
+	// are part of the sample, their result is the status of the installation of
+	// the current version of the profile.
+	var desiredState, hostProfilesJoin string
+	switch {
+	case strings.HasPrefix(profileUUID, fleet.MDMAppleProfileUUIDPrefix):
+		filter := "mae.profile_uuid = ? AND " + hostNotPendingApprovalCondition
+		desiredState = fmt.Sprintf(generateDesiredStateQuery("profile"), filter, filter)
+		hostProfilesJoin = `host_mdm_apple_profiles hmp
+			ON hmp.profile_uuid = ds.profile_uuid AND hmp.host_uuid = ds.host_uuid AND
+				hmp.operation_type = ? AND hmp.checksum = ds.checksum`
+	case strings.HasPrefix(profileUUID, fleet.MDMWindowsProfileUUIDPrefix):
+		filter := "mwcp.profile_uuid = ? AND " + hostNotPendingApprovalCondition
+		desiredState = fmt.Sprintf(windowsMDMProfilesDesiredStateQuery, filter, filter)
+		hostProfilesJoin = `host_mdm_windows_profiles hmp
+			ON hmp.profile_uuid = ds.profile_uuid AND hmp.host_uuid = ds.host_uuid AND
+				hmp.operation_type = ?`
+	default:
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: fmt.Sprintf("Profile %s does not support rollouts.", profileUUID),
+		})
+	}
+
+	stmt := fmt.Sprintf(`
+	SELECT
+		COUNT(*) AS canary_hosts,
+		COALESCE(SUM(hmp.status = ?), 0) AS verified_hosts,
+		COALESCE(SUM(hmp.status = ?), 0) AS failed_hosts
+	FROM ( %s ) AS ds
+		JOIN mdm_profile_rollouts mpr
+			ON mpr.profile_uuid = ds.profile_uuid
+		LEFT JOIN %s
+	WHERE
+		%s`, desiredState, hostProfilesJoin, profileRolloutCanaryCondition("mpr", "ds.profile_uuid", "ds.host_uuid"))
+
+	var res fleet.MDMProfileRolloutResults
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &res, stmt,
+		fleet.MDMDeliveryVerified, fleet.MDMDeliveryFailed, profileUUID, profileUUID, fleet.MDMOperationTypeInstall,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get profile rollout results")
+	}
+	return &res, nil
+}
+
+func (ds *Datastore) UpdateMDMProfileRolloutStatus(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error {
+	// going back to the canary status restarts the rollout, the other
+	// statuses finish it.
+	const stmt = `
+	UPDATE mdm_profile_rollouts
+	SET
+		status = ?,
+		reason = ?,
+		started_at = IF(?, CURRENT_TIMESTAMP, started_at),
+		finished_at = IF(?, NULL, CURRENT_TIMESTAMP)
+	WHERE profile_uuid = ?`
+
+	restart := status == fleet.MDMProfileRolloutCanary
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, status, reason, restart, restart, profileUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "update profile rollout status")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupMDMProfileRollouts(ctx context.Context) error {
+	const stmt = `
+	DELETE mpr FROM mdm_profile_rollouts mpr
+	WHERE
+		NOT EXISTS (SELECT 1 FROM mdm_apple_configuration_profiles macp WHERE macp.profile_uuid = mpr.profile_uuid) AND
+		NOT EXISTS (SELECT 1 FROM mdm_windows_configuration_profiles mwcp WHERE mwcp.profile_uuid = mpr.profile_uuid)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup profile rollouts")
+	}
+	return nil
+}
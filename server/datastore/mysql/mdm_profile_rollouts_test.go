@@ -0,0 +1,247 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRollouts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"AppleCanary", testMDMProfileRolloutsAppleCanary},
+		{"WindowsCanary", testMDMProfileRolloutsWindowsCanary},
+		{"BatchSet", testMDMProfileRolloutsBatchSet},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+// canaryHostUUIDs returns the UUIDs of the hosts that are part of the canary
+// sample of the profile, computed the same way as in the database.
+func canaryHostUUIDs(profileUUID string, hosts []*fleet.Host, percentage uint) []string {
+	var uuids []string
+	for _, h := range hosts {
+		if crc32.ChecksumIEEE([]byte(profileUUID+h.UUID))%100 < uint32(percentage) {
+			uuids = append(uuids, h.UUID)
+		}
+	}
+	return uuids
+}
+
+func testMDMProfileRolloutsAppleCanary(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	hosts := make([]*fleet.Host, 50)
+	for i := range hosts {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:      fmt.Sprintf("host%d", i),
+			OsqueryHostID: ptr.String(fmt.Sprintf("osquery%d", i)),
+			NodeKey:       ptr.String(fmt.Sprintf("nodekey%d", i)),
+			UUID:          fmt.Sprintf("uuid-%d", i),
+			Platform:      "darwin",
+		})
+		require.NoError(t, err)
+		nanoEnroll(t, ds, h, false)
+		hosts[i] = h
+	}
+
+	cp := configProfileForTest(t, "p1", "p1", "p1")
+	cp.Rollout = &fleet.MDMProfileRolloutSettings{CanaryPercentage: 30, FailureThresholdPercentage: 10, TimeoutMinutes: 60}
+	prof, err := ds.NewMDMAppleConfigProfile(ctx, *cp)
+	require.NoError(t, err)
+	prof.Checksum = cp.Checksum
+
+	canary := canaryHostUUIDs(prof.ProfileUUID, hosts, 30)
+	require.NotEmpty(t, canary)
+	require.Less(t, len(canary), len(hosts))
+
+	// only the canary hosts get the profile
+	toInstall, err := ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	var got []string
+	for _, p := range toInstall {
+		require.Equal(t, prof.ProfileUUID, p.ProfileUUID)
+		got = append(got, p.HostUUID)
+	}
+	require.ElementsMatch(t, canary, got)
+
+	rollout, err := ds.GetMDMProfileRollout(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Equal(t, "p1", rollout.ProfileName)
+	require.Nil(t, rollout.TeamID)
+	require.Equal(t, fleet.MDMProfileRolloutCanary, rollout.Status)
+	require.Equal(t, *cp.Rollout, rollout.MDMProfileRolloutSettings)
+	require.Nil(t, rollout.FinishedAt)
+	require.Equal(t, fleet.MDMProfileRolloutResults{CanaryHosts: uint(len(canary))}, rollout.Results)
+
+	active, err := ds.ListActiveMDMProfileRollouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, prof.ProfileUUID, active[0].ProfileUUID)
+
+	// the first canary host failed, the others are verified
+	var canaryHosts []*fleet.Host
+	for _, h := range hosts {
+		for _, u := range canary {
+			if h.UUID == u {
+				canaryHosts = append(canaryHosts, h)
+			}
+		}
+	}
+	upsertHostCPs(canaryHosts[:1], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryFailed, ctx, ds, t)
+	upsertHostCPs(canaryHosts[1:], []*fleet.MDMAppleConfigProfile{prof}, fleet.MDMOperationTypeInstall, &fleet.MDMDeliveryVerified, ctx, ds, t)
+
+	res, err := ds.GetMDMProfileRolloutResults(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutResults{
+		CanaryHosts: uint(len(canary)), VerifiedHosts: uint(len(canary) - 1), FailedHosts: 1,
+	}, *res)
+
+	// halting the rollout keeps the profile on the canary hosts only
+	require.NoError(t, ds.UpdateMDMProfileRolloutStatus(ctx, prof.ProfileUUID, fleet.MDMProfileRolloutHalted, "halted"))
+	toInstall, err = ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	require.Empty(t, toInstall)
+	rollout, err = ds.GetMDMProfileRollout(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutHalted, rollout.Status)
+	require.Equal(t, "halted", rollout.Reason)
+	require.NotNil(t, rollout.FinishedAt)
+	active, err = ds.ListActiveMDMProfileRollouts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, active)
+
+	// completing the rollout delivers the profile to all the other hosts
+	require.NoError(t, ds.UpdateMDMProfileRolloutStatus(ctx, prof.ProfileUUID, fleet.MDMProfileRolloutCompleted, "completed"))
+	toInstall, err = ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	require.Len(t, toInstall, len(hosts)-len(canary))
+
+	// restarting the rollout goes back to the canary hosts
+	require.NoError(t, ds.UpdateMDMProfileRolloutStatus(ctx, prof.ProfileUUID, fleet.MDMProfileRolloutCanary, "restarted"))
+	toInstall, err = ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	require.Empty(t, toInstall)
+	rollout, err = ds.GetMDMProfileRollout(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutCanary, rollout.Status)
+	require.Nil(t, rollout.FinishedAt)
+
+	// the rollout is deleted with its profile
+	require.NoError(t, ds.DeleteMDMAppleConfigProfile(ctx, prof.ProfileUUID))
+	require.NoError(t, ds.CleanupMDMProfileRollouts(ctx))
+	_, err = ds.GetMDMProfileRollout(ctx, prof.ProfileUUID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testMDMProfileRolloutsWindowsCanary(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team"})
+	require.NoError(t, err)
+
+	hosts := make([]*fleet.Host, 50)
+	for i := range hosts {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:      fmt.Sprintf("host%d", i),
+			OsqueryHostID: ptr.String(fmt.Sprintf("osquery%d", i)),
+			NodeKey:       ptr.String(fmt.Sprintf("nodekey%d", i)),
+			UUID:          fmt.Sprintf("uuid-%d", i),
+			Platform:      "windows",
+			TeamID:        &team.ID,
+		})
+		require.NoError(t, err)
+		windowsEnroll(t, ds, h)
+		hosts[i] = h
+	}
+
+	prof, err := ds.NewMDMWindowsConfigProfile(ctx, fleet.MDMWindowsConfigProfile{
+		Name:    "w1",
+		TeamID:  &team.ID,
+		SyncML:  []byte("<Replace></Replace>"),
+		Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 50, FailureThresholdPercentage: 10, TimeoutMinutes: 60},
+	})
+	require.NoError(t, err)
+
+	canary := canaryHostUUIDs(prof.ProfileUUID, hosts, 50)
+	toInstall, err := ds.ListMDMWindowsProfilesToInstall(ctx)
+	require.NoError(t, err)
+	var got []string
+	for _, p := range toInstall {
+		got = append(got, p.HostUUID)
+	}
+	require.ElementsMatch(t, canary, got)
+
+	rollout, err := ds.GetMDMProfileRollout(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Equal(t, "w1", rollout.ProfileName)
+	require.Equal(t, &team.ID, rollout.TeamID)
+	require.Equal(t, fleet.MDMProfileRolloutResults{CanaryHosts: uint(len(canary))}, rollout.Results)
+
+	require.NoError(t, ds.UpdateMDMProfileRolloutStatus(ctx, prof.ProfileUUID, fleet.MDMProfileRolloutCompleted, "completed"))
+	toInstall, err = ds.ListMDMWindowsProfilesToInstall(ctx)
+	require.NoError(t, err)
+	require.Len(t, toInstall, len(hosts))
+}
+
+func testMDMProfileRolloutsBatchSet(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	settings := &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 5, TimeoutMinutes: 60}
+	macProf := configProfileForTest(t, "N1", "I1", "a")
+	macProf.Rollout = settings
+	winProf := &fleet.MDMWindowsConfigProfile{Name: "W1", SyncML: []byte("<Replace></Replace>"), Rollout: settings}
+	require.NoError(t, ds.BatchSetMDMProfiles(ctx, nil, []*fleet.MDMAppleConfigProfile{macProf}, []*fleet.MDMWindowsConfigProfile{winProf}, nil))
+
+	profs, _, err := ds.ListMDMConfigProfiles(ctx, nil, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, profs, 2)
+	uuids := map[string]string{}
+	for _, p := range profs {
+		uuids[p.Name] = p.ProfileUUID
+	}
+	for _, u := range uuids {
+		rollout, err := ds.GetMDMProfileRollout(ctx, u)
+		require.NoError(t, err)
+		require.Equal(t, fleet.MDMProfileRolloutCanary, rollout.Status)
+		require.Equal(t, *settings, rollout.MDMProfileRolloutSettings)
+		require.NoError(t, ds.UpdateMDMProfileRolloutStatus(ctx, u, fleet.MDMProfileRolloutCompleted, "completed"))
+	}
+
+	// changing only the settings keeps the status of the rollouts
+	newSettings := &fleet.MDMProfileRolloutSettings{CanaryPercentage: 20, FailureThresholdPercentage: 5, TimeoutMinutes: 60}
+	macProf.Rollout, winProf.Rollout = newSettings, newSettings
+	require.NoError(t, ds.BatchSetMDMProfiles(ctx, nil, []*fleet.MDMAppleConfigProfile{macProf}, []*fleet.MDMWindowsConfigProfile{winProf}, nil))
+	for _, u := range uuids {
+		rollout, err := ds.GetMDMProfileRollout(ctx, u)
+		require.NoError(t, err)
+		require.Equal(t, fleet.MDMProfileRolloutCompleted, rollout.Status)
+		require.EqualValues(t, 20, rollout.CanaryPercentage)
+	}
+
+	// changing the contents restarts the rollout, removing the settings deletes it
+	winProf.SyncML = []byte("<Replace><Item></Item></Replace>")
+	macProf.Rollout = nil
+	require.NoError(t, ds.BatchSetMDMProfiles(ctx, nil, []*fleet.MDMAppleConfigProfile{macProf}, []*fleet.MDMWindowsConfigProfile{winProf}, nil))
+	rollout, err := ds.GetMDMProfileRollout(ctx, uuids["W1"])
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutCanary, rollout.Status)
+	require.Nil(t, rollout.FinishedAt)
+	_, err = ds.GetMDMProfileRollout(ctx, uuids["N1"])
+	require.True(t, fleet.IsNotFound(err))
+}
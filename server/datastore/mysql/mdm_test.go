@@ -43,6 +43,7 @@ func TestMDMShared(t *testing.T) {
 			testGetHostMDMProfilesExpectedForVerification,
 		},
 		{"TestBatchSetProfileLabelAssociations", testBatchSetProfileLabelAssociations},
+		{"TestPreviewMDMProfileTargets", testPreviewMDMProfileTargets},
 		{"TestBatchSetProfilesTransactionError", testBatchSetMDMProfilesTransactionError},
 		{"TestMDMEULA", testMDMEULA},
 		{"TestGetHostCertAssociationsToExpire", testSCEPRenewalHelpers},
@@ -215,6 +216,56 @@ func testMDMCommands(t *testing.T, ds *Datastore) {
 	require.Equal(t, winCmd.CommandUUID, cmds[1].CommandUUID)
 	require.Equal(t, winCmd.TargetLocURI, cmds[1].RequestType)
 	require.Equal(t, "200", cmds[1].Status)
+
+	// move the macOS host to a team
+	tm, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	err = ds.AddHostsToTeam(ctx, &tm.ID, []uint{macH.ID})
+	require.NoError(t, err)
+
+	listUUIDs := func(tmFilter fleet.TeamFilter, opts fleet.MDMCommandListOptions) []string {
+		opts.ListOptions = fleet.ListOptions{OrderKey: "hostname"}
+		cmds, err := ds.ListMDMCommands(ctx, tmFilter, &opts)
+		require.NoError(t, err)
+		uuids := make([]string, 0, len(cmds))
+		for _, c := range cmds {
+			uuids = append(uuids, c.CommandUUID)
+		}
+		return uuids
+	}
+	adminFilter := fleet.TeamFilter{User: test.UserAdmin}
+	now := time.Now().UTC()
+
+	cases := []struct {
+		desc     string
+		tmFilter fleet.TeamFilter
+		opts     fleet.MDMCommandListOptions
+		want     []string
+	}{
+		{"no filter", adminFilter, fleet.MDMCommandListOptions{}, []string{appleCmdUUID, winCmd.CommandUUID}},
+		{
+			"team user", fleet.TeamFilter{User: &fleet.User{Teams: []fleet.UserTeam{{Team: *tm, Role: fleet.RoleObserver}}}, IncludeObserver: true},
+			fleet.MDMCommandListOptions{}, []string{appleCmdUUID},
+		},
+		{"acknowledged", adminFilter, fleet.MDMCommandListOptions{Status: fleet.MDMCommandStatusFilterAcknowledged}, []string{appleCmdUUID, winCmd.CommandUUID}},
+		{"pending", adminFilter, fleet.MDMCommandListOptions{Status: fleet.MDMCommandStatusFilterPending}, []string{}},
+		{"error", adminFilter, fleet.MDMCommandListOptions{Status: fleet.MDMCommandStatusFilterError}, []string{}},
+		{"request type", adminFilter, fleet.MDMCommandListOptions{RequestType: "ProfileList"}, []string{appleCmdUUID}},
+		{"team", adminFilter, fleet.MDMCommandListOptions{TeamID: &tm.ID}, []string{appleCmdUUID}},
+		{"no team", adminFilter, fleet.MDMCommandListOptions{TeamID: ptr.Uint(0)}, []string{winCmd.CommandUUID}},
+		{"updated after", adminFilter, fleet.MDMCommandListOptions{UpdatedAfter: ptr.Time(now.Add(time.Hour))}, []string{}},
+		{"updated before", adminFilter, fleet.MDMCommandListOptions{UpdatedBefore: ptr.Time(now.Add(time.Hour))}, []string{appleCmdUUID, winCmd.CommandUUID}},
+		{
+			"combined", adminFilter,
+			fleet.MDMCommandListOptions{Status: fleet.MDMCommandStatusFilterAcknowledged, RequestType: winCmd.TargetLocURI, TeamID: ptr.Uint(0)},
+			[]string{winCmd.CommandUUID},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, listUUIDs(c.tmFilter, c.opts))
+		})
+	}
 }
 
 func testBatchSetMDMProfiles(t *testing.T, ds *Datastore) {
@@ -6198,6 +6249,8 @@ func testSCEPRenewalHelpers(t *testing.T, ds *Datastore) {
 	require.Equal(t, h2.UUID, assocs[1].HostUUID)
 	require.Equal(t, h3.UUID, assocs[2].HostUUID)
 	require.Equal(t, h4.UUID, assocs[3].HostUUID)
+	// the topic of the hosts' enrollment is returned with the associations
+	require.Equal(t, h1.UUID+".topic", assocs[0].Topic)
 
 	checkSCEPRenew := func(assoc fleet.SCEPIdentityAssociation, want *string) {
 		var got *string
@@ -6250,6 +6303,16 @@ func testSCEPRenewalHelpers(t *testing.T, ds *Datastore) {
 	)
 	require.ErrorContains(t, err, "this function can only be used to update existing associations")
 
+	assoc, err := ds.GetHostSCEPIdentityAssociation(ctx, h3.UUID)
+	require.NoError(t, err)
+	require.Equal(t, h3.UUID, assoc.HostUUID)
+	require.Equal(t, assocs[2].SHA256, assoc.SHA256)
+	require.Equal(t, "bar", assoc.RenewCommandUUID)
+	require.Equal(t, h3.UUID+".topic", assoc.Topic)
+
+	_, err = ds.GetHostSCEPIdentityAssociation(ctx, "does-not-exist")
+	require.True(t, fleet.IsNotFound(err))
+
 	err = ds.CleanSCEPRenewRefs(ctx, "does-not-exist")
 	require.Error(t, err)
 
@@ -6679,3 +6742,103 @@ func testMDMProfilesSummaryAndHostFilters(t *testing.T, ds *Datastore) {
 
 	cleanupTables(t)
 }
+
+func testPreviewMDMProfileTargets(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team"})
+	require.NoError(t, err)
+
+	newLabel := func(name string, membership fleet.LabelMembershipType) fleet.ConfigurationProfileLabel {
+		lbl, err := ds.NewLabel(ctx, &fleet.Label{Name: name, Query: "select 1", LabelMembershipType: membership})
+		require.NoError(t, err)
+		return fleet.ConfigurationProfileLabel{LabelName: lbl.Name, LabelID: lbl.ID}
+	}
+	lblA := newLabel("a", fleet.LabelMembershipTypeManual)
+	lblB := newLabel("b", fleet.LabelMembershipTypeManual)
+	lblC := newLabel("c", fleet.LabelMembershipTypeManual)
+
+	// macOS hosts with no team: h1 in a, h2 in a and b, h3 in c, h4 in no label
+	var macHosts []*fleet.Host
+	for i := 0; i < 4; i++ {
+		h := test.NewHost(t, ds, fmt.Sprintf("mac%d", i), "", fmt.Sprintf("mac-key%d", i), fmt.Sprintf("mac-uuid%d", i), time.Now())
+		nanoEnroll(t, ds, h, false)
+		macHosts = append(macHosts, h)
+	}
+	require.NoError(t, ds.AddLabelsToHost(ctx, macHosts[0].ID, []uint{lblA.LabelID}))
+	require.NoError(t, ds.AddLabelsToHost(ctx, macHosts[1].ID, []uint{lblA.LabelID, lblB.LabelID}))
+	require.NoError(t, ds.AddLabelsToHost(ctx, macHosts[2].ID, []uint{lblC.LabelID}))
+
+	// a macOS host not enrolled in MDM and one in a team are never targeted
+	test.NewHost(t, ds, "mac-unenrolled", "", "mac-unenrolled-key", "mac-unenrolled-uuid", time.Now())
+	teamHost := test.NewHost(t, ds, "mac-team", "", "mac-team-key", "mac-team-uuid", time.Now())
+	nanoEnroll(t, ds, teamHost, false)
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{teamHost.ID}))
+
+	// a Windows host in a
+	winHost := test.NewHost(t, ds, "win", "", "win-key", "win-uuid", time.Now(), test.WithPlatform("windows"))
+	windowsEnroll(t, ds, winHost)
+	require.NoError(t, ds.AddLabelsToHost(ctx, winHost.ID, []uint{lblA.LabelID}))
+
+	hostIDs := func(preview *fleet.MDMProfileTargetsPreview) []uint {
+		var ids []uint
+		for _, h := range preview.Hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name       string
+		teamID     *uint
+		platform   string
+		includeAll []fleet.ConfigurationProfileLabel
+		includeAny []fleet.ConfigurationProfileLabel
+		excludeAny []fleet.ConfigurationProfileLabel
+		want       []uint
+	}{
+		{"no labels", nil, "darwin", nil, nil, nil, []uint{macHosts[0].ID, macHosts[1].ID, macHosts[2].ID, macHosts[3].ID}},
+		{"team", &team.ID, "darwin", nil, nil, nil, []uint{teamHost.ID}},
+		{"windows", nil, "windows", nil, nil, nil, []uint{winHost.ID}},
+		{"include all", nil, "darwin", []fleet.ConfigurationProfileLabel{lblA, lblB}, nil, nil, []uint{macHosts[1].ID}},
+		{"include any", nil, "darwin", nil, []fleet.ConfigurationProfileLabel{lblB, lblC}, nil, []uint{macHosts[1].ID, macHosts[2].ID}},
+		{"exclude any", nil, "darwin", nil, nil, []fleet.ConfigurationProfileLabel{lblA}, []uint{macHosts[2].ID, macHosts[3].ID}},
+		{
+			"include all and exclude any", nil, "darwin",
+			[]fleet.ConfigurationProfileLabel{lblA}, nil, []fleet.ConfigurationProfileLabel{lblB},
+			[]uint{macHosts[0].ID},
+		},
+		{
+			"include any and exclude any", nil, "darwin",
+			nil, []fleet.ConfigurationProfileLabel{lblA, lblC}, []fleet.ConfigurationProfileLabel{lblB},
+			[]uint{macHosts[0].ID, macHosts[2].ID},
+		},
+		{"windows exclude", nil, "windows", nil, nil, []fleet.ConfigurationProfileLabel{lblA}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			labels := fleet.JoinConfigurationProfileLabels(c.includeAll, c.includeAny, c.excludeAny)
+			preview, err := ds.PreviewMDMProfileTargets(ctx, c.teamID, c.platform, labels)
+			require.NoError(t, err)
+			require.EqualValues(t, len(c.want), preview.HostsCount)
+			require.Equal(t, c.want, hostIDs(preview))
+		})
+	}
+
+	// the label sets of a profile are saved and loaded back
+	prof, err := ds.NewMDMAppleConfigProfile(ctx, fleet.MDMAppleConfigProfile{
+		Name:         "p1",
+		Identifier:   "i1",
+		Mobileconfig: mobileconfig.Mobileconfig("p1"),
+		Labels:       fleet.JoinConfigurationProfileLabels([]fleet.ConfigurationProfileLabel{lblA}, []fleet.ConfigurationProfileLabel{lblB}, []fleet.ConfigurationProfileLabel{lblC}),
+	})
+	require.NoError(t, err)
+	got, err := ds.GetMDMAppleConfigProfile(ctx, prof.ProfileUUID)
+	require.NoError(t, err)
+	require.Len(t, got.Labels, 1)
+	require.Equal(t, lblA.LabelID, got.Labels[0].LabelID)
+	require.Len(t, got.LabelsIncludeAny, 1)
+	require.Equal(t, lblB.LabelID, got.LabelsIncludeAny[0].LabelID)
+	require.Len(t, got.LabelsExcludeAny, 1)
+	require.Equal(t, lblC.LabelID, got.LabelsExcludeAny[0].LabelID)
+}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/xml"
@@ -742,10 +743,8 @@ WHERE
 	if err != nil {
 		return nil, err
 	}
-	if len(labels) > 0 {
-		// ensure we leave Labels nil if there are none
-		res.Labels = labels
-	}
+	// labels are left nil if there are none
+	res.Labels, res.LabelsIncludeAny, res.LabelsExcludeAny = fleet.SplitConfigurationProfileLabels(labels)
 
 	return &res, nil
 }
@@ -1120,7 +1119,7 @@ GROUP BY
 	return counts, nil
 }
 
-const windowsMDMProfilesDesiredStateQuery = `
+var windowsMDMProfilesDesiredStateQuery = `
 	-- non label-based profiles
 	SELECT
 		mwcp.profile_uuid,
@@ -1131,7 +1130,7 @@ const windowsMDMProfilesDesiredStateQuery = `
 	FROM
 		mdm_windows_configuration_profiles mwcp
 			JOIN hosts h
-				ON h.team_id = mwcp.team_id OR (h.team_id IS NULL AND mwcp.team_id = 0)
+				ON ` + profileTargetsHostTeamCondition("windows", "mwcp", "h") + `
 			JOIN mdm_windows_enrollments mwe
 				ON mwe.host_uuid = h.uuid
 	WHERE
@@ -1145,7 +1144,7 @@ const windowsMDMProfilesDesiredStateQuery = `
 
 	UNION
 
-	-- label-based profiles
+	-- label-based profiles that target the host
 	SELECT
 		mwcp.profile_uuid,
 		mwcp.name,
@@ -1155,20 +1154,22 @@ const windowsMDMProfilesDesiredStateQuery = `
 	FROM
 		mdm_windows_configuration_profiles mwcp
 			JOIN hosts h
-				ON h.team_id = mwcp.team_id OR (h.team_id IS NULL AND mwcp.team_id = 0)
+				ON ` + profileTargetsHostTeamCondition("windows", "mwcp", "h") + `
 			JOIN mdm_windows_enrollments mwe
 				ON mwe.host_uuid = h.uuid
 			JOIN mdm_configuration_profile_labels mcpl
 				ON mcpl.windows_profile_uuid = mwcp.profile_uuid
 			LEFT OUTER JOIN label_membership lm
 				ON lm.label_id = mcpl.label_id AND lm.host_id = h.id
+			LEFT OUTER JOIN labels lbl
+				ON lbl.id = mcpl.label_id
 	WHERE
 		h.platform = 'windows' AND
 		( %s )
 	GROUP BY
-		mwcp.profile_uuid, mwcp.name, h.uuid
+		mwcp.profile_uuid, mwcp.name, h.uuid, h.label_updated_at
 	HAVING
-		count_profile_labels > 0 AND count_host_labels = count_profile_labels
+		` + profileLabelsTargetingCondition("mcpl", "h") + `
 `
 
 func (ds *Datastore) ListMDMWindowsProfilesToInstall(ctx context.Context) ([]*fleet.MDMWindowsProfilePayload, error) {
@@ -1212,6 +1213,9 @@ func listMDMWindowsProfilesToInstallDB(
 	// considered for installation. This means that a broken label-based profile,
 	// where one of the labels does not exist anymore, will not be considered for
 	// installation.
+	//
+	// Hosts that are pending enrollment approval are excluded from the desired
+	// state, so that no profile is installed on them until they are approved.
 
 	query := fmt.Sprintf(`
 	SELECT
@@ -1222,15 +1226,20 @@ func listMDMWindowsProfilesToInstallDB(
 		LEFT JOIN host_mdm_windows_profiles hmwp
 			ON hmwp.profile_uuid = ds.profile_uuid AND hmwp.host_uuid = ds.host_uuid
 	WHERE
-		-- profiles in A but not in B
-		( hmwp.profile_uuid IS NULL AND hmwp.host_uuid IS NULL ) OR
-		-- profiles in A and B with operation type "install" and NULL status
-		( hmwp.host_uuid IS NOT NULL AND hmwp.operation_type = ? AND hmwp.status IS NULL )
-`, windowsMDMProfilesDesiredStateQuery)
+		(
+			-- profiles in A but not in B
+			( hmwp.profile_uuid IS NULL AND hmwp.host_uuid IS NULL ) OR
+			-- profiles in A and B with operation type "install" and NULL status
+			( hmwp.host_uuid IS NOT NULL AND hmwp.operation_type = ? AND hmwp.status IS NULL )
+		) AND
+		-- profiles in a staged rollout are only installed on the canary hosts
+		-- until the rollout is completed
+		%s
+`, windowsMDMProfilesDesiredStateQuery, profileRolloutGateCondition("ds.profile_uuid", "ds.host_uuid"))
 
-	hostFilter := "TRUE"
+	hostFilter := hostNotPendingApprovalCondition
 	if len(hostUUIDs) > 0 {
-		hostFilter = "h.uuid IN (?)"
+		hostFilter = "h.uuid IN (?) AND " + hostNotPendingApprovalCondition
 	}
 
 	var err error
@@ -1533,13 +1542,20 @@ INSERT INTO
 			}
 		}
 
-		for i := range cp.Labels {
-			cp.Labels[i].ProfileUUID = profileUUID
+		labels := fleet.JoinConfigurationProfileLabels(cp.Labels, cp.LabelsIncludeAny, cp.LabelsExcludeAny)
+		for i := range labels {
+			labels[i].ProfileUUID = profileUUID
 		}
-		if err := batchSetProfileLabelAssociationsDB(ctx, tx, cp.Labels, "windows"); err != nil {
+		if err := batchSetProfileLabelAssociationsDB(ctx, tx, labels, "windows"); err != nil {
 			return ctxerr.Wrap(ctx, err, "inserting windows profile label associations")
 		}
 
+		if cp.Rollout != nil {
+			if err := upsertMDMProfileRolloutDB(ctx, tx, profileUUID, *cp.Rollout, true); err != nil {
+				return ctxerr.Wrap(ctx, err, "inserting windows profile rollout")
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -1551,6 +1567,7 @@ INSERT INTO
 		Name:        cp.Name,
 		SyncML:      cp.SyncML,
 		TeamID:      cp.TeamID,
+		Rollout:     cp.Rollout,
 	}, nil
 }
 
@@ -1686,6 +1703,11 @@ ON DUPLICATE KEY UPDATE
 		}
 	}
 
+	existingProfsByName := make(map[string]*fleet.MDMWindowsConfigProfile, len(existingProfiles))
+	for _, p := range existingProfiles {
+		existingProfsByName[p.Name] = p
+	}
+
 	// figure out if we need to delete any profiles
 	keepNames := make([]string, 0, len(incomingNames))
 	for _, p := range existingProfiles {
@@ -1744,6 +1766,8 @@ ON DUPLICATE KEY UPDATE
 	// between macOS and Windows, but at the time of this
 	// implementation we're under tight time constraints.
 	incomingLabels := []fleet.ConfigurationProfileLabel{}
+	// the rollout of a profile restarts if it is new or its contents changed
+	incomingRollouts := []mdmProfileRolloutSpec{}
 	if len(incomingNames) > 0 {
 		var newlyInsertedProfs []*fleet.MDMWindowsConfigProfile
 		// load current profiles (again) that match the incoming profiles by name to grab their uuids
@@ -1767,10 +1791,17 @@ ON DUPLICATE KEY UPDATE
 				return ctxerr.Wrapf(ctx, err, "profile %q is in the database but was not incoming", newlyInsertedProf.Name)
 			}
 
-			for _, label := range incomingProf.Labels {
+			for _, label := range fleet.JoinConfigurationProfileLabels(incomingProf.Labels, incomingProf.LabelsIncludeAny, incomingProf.LabelsExcludeAny) {
 				label.ProfileUUID = newlyInsertedProf.ProfileUUID
 				incomingLabels = append(incomingLabels, label)
 			}
+
+			existingProf := existingProfsByName[newlyInsertedProf.Name]
+			incomingRollouts = append(incomingRollouts, mdmProfileRolloutSpec{
+				profileUUID: newlyInsertedProf.ProfileUUID,
+				settings:    incomingProf.Rollout,
+				restart:     existingProf == nil || !bytes.Equal(existingProf.SyncML, incomingProf.SyncML),
+			})
 		}
 	}
 
@@ -1782,6 +1813,14 @@ ON DUPLICATE KEY UPDATE
 		return ctxerr.Wrap(ctx, err, "inserting windows profile label associations")
 	}
 
+	// insert/delete the staged rollouts
+	if err := batchSetMDMProfileRolloutsDB(ctx, tx, incomingRollouts); err != nil || strings.HasPrefix(ds.testBatchSetMDMWindowsProfilesErr, "rollouts") {
+		if err == nil {
+			err = errors.New(ds.testBatchSetMDMWindowsProfilesErr)
+		}
+		return ctxerr.Wrap(ctx, err, "setting windows profile rollouts")
+	}
+
 	return nil
 }
 
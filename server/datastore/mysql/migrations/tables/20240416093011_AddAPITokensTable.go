@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240416093011, Down_20240416093011)
+}
+
+func Up_20240416093011(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE api_tokens (
+	id                 INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name               VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	team_id            INT UNSIGNED NOT NULL,
+	scope              VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+	token_hash         CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	created_by_user_id INT UNSIGNED NOT NULL,
+	last_used_at       TIMESTAMP NULL DEFAULT NULL,
+	created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_api_tokens_token_hash (token_hash),
+	UNIQUE KEY idx_api_tokens_team_name (team_id, name),
+	CONSTRAINT fk_api_tokens_team_id FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE,
+	CONSTRAINT fk_api_tokens_created_by_user_id FOREIGN KEY (created_by_user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create api_tokens table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240416093011(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240416093011(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	teamID := execNoErrLastID(t, db, `INSERT INTO teams (name) VALUES ('team1')`)
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'pw', 'salt')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO api_tokens (name, team_id, scope, token_hash, created_by_user_id) VALUES (?, ?, ?, ?, ?)`,
+		"tok", teamID, "scripts", "abc", userID)
+
+	// name must be unique per team
+	_, err := db.Exec(`INSERT INTO api_tokens (name, team_id, scope, token_hash, created_by_user_id) VALUES (?, ?, ?, ?, ?)`,
+		"tok", teamID, "mdm", "def", userID)
+	require.Error(t, err)
+
+	// tokens are deleted with their team
+	execNoErr(t, db, `DELETE FROM teams WHERE id = ?`, teamID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM api_tokens`))
+	require.Zero(t, count)
+}
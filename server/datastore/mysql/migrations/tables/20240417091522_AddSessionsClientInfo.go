@@ -0,0 +1,25 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240417091522, Down_20240417091522)
+}
+
+func Up_20240417091522(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+ALTER TABLE sessions
+	ADD COLUMN ip_address VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	ADD COLUMN user_agent VARCHAR(512) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add client info columns to sessions: %w", err)
+	}
+	return nil
+}
+
+func Down_20240417091522(tx *sql.Tx) error {
+	return nil
+}
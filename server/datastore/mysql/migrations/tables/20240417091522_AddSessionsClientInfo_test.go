@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240417091522(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'pw', 'salt')`)
+	execNoErr(t, db, "INSERT INTO sessions (user_id, `key`) VALUES (?, ?)", userID, "key1")
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	// existing sessions get empty client info
+	var info struct {
+		IPAddress string `db:"ip_address"`
+		UserAgent string `db:"user_agent"`
+	}
+	require.NoError(t, db.Get(&info, "SELECT ip_address, user_agent FROM sessions WHERE `key` = ?", "key1"))
+	require.Empty(t, info.IPAddress)
+	require.Empty(t, info.UserAgent)
+
+	execNoErr(t, db, "INSERT INTO sessions (user_id, `key`, ip_address, user_agent) VALUES (?, ?, ?, ?)", userID, "key2", "10.0.0.1", "curl/8.0")
+	require.NoError(t, db.Get(&info, "SELECT ip_address, user_agent FROM sessions WHERE `key` = ?", "key2"))
+	require.Equal(t, "10.0.0.1", info.IPAddress)
+	require.Equal(t, "curl/8.0", info.UserAgent)
+}
@@ -0,0 +1,91 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240418102347, Down_20240418102347)
+}
+
+func Up_20240418102347(tx *sql.Tx) error {
+	stmts := []struct {
+		desc string
+		stmt string
+	}{
+		{"add mfa_enabled to users", `
+ALTER TABLE users
+	ADD COLUMN mfa_enabled TINYINT(1) NOT NULL DEFAULT '0'`},
+
+		// the TOTP secrets are encrypted with the server private key.
+		{"create user_totp_secrets", `
+CREATE TABLE user_totp_secrets (
+	user_id          INT UNSIGNED NOT NULL,
+	encrypted_secret VARBINARY(255) NOT NULL,
+	confirmed_at     TIMESTAMP NULL DEFAULT NULL,
+	last_used_step   BIGINT NOT NULL DEFAULT '0',
+	created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (user_id),
+	CONSTRAINT fk_user_totp_secrets_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+
+		{"create user_webauthn_credentials", `
+CREATE TABLE user_webauthn_credentials (
+	id            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id       INT UNSIGNED NOT NULL,
+	name          VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	credential_id VARBINARY(255) NOT NULL,
+	public_key    BLOB NOT NULL,
+	sign_count    INT UNSIGNED NOT NULL DEFAULT '0',
+	created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at  TIMESTAMP NULL DEFAULT NULL,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_user_webauthn_credentials_credential_id (credential_id),
+	KEY idx_user_webauthn_credentials_user_id (user_id),
+	CONSTRAINT fk_user_webauthn_credentials_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+
+		{"create user_mfa_recovery_codes", `
+CREATE TABLE user_mfa_recovery_codes (
+	id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id    INT UNSIGNED NOT NULL,
+	code_hash  CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	used_at    TIMESTAMP NULL DEFAULT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_user_mfa_recovery_codes_user_code (user_id, code_hash),
+	CONSTRAINT fk_user_mfa_recovery_codes_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+
+		// attempts counts the second factors submitted for a login challenge,
+		// so that it can be invalidated after a few failed attempts.
+		{"create mfa_challenges", `
+CREATE TABLE mfa_challenges (
+	token              VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	user_id            INT UNSIGNED NOT NULL,
+	purpose            VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+	webauthn_challenge VARBINARY(64) NULL DEFAULT NULL,
+	created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	attempts           TINYINT UNSIGNED NOT NULL DEFAULT '0',
+
+	PRIMARY KEY (token),
+	KEY idx_mfa_challenges_created_at (created_at),
+	CONSTRAINT fk_mfa_challenges_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+	}
+
+	for _, s := range stmts {
+		if _, err := tx.Exec(s.stmt); err != nil {
+			return fmt.Errorf("%s: %w", s.desc, err)
+		}
+	}
+	return nil
+}
+
+func Down_20240418102347(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240418102347(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'pw', 'salt')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var mfaEnabled bool
+	require.NoError(t, db.Get(&mfaEnabled, `SELECT mfa_enabled FROM users WHERE id = ?`, userID))
+	require.False(t, mfaEnabled)
+
+	execNoErr(t, db, `INSERT INTO user_totp_secrets (user_id, encrypted_secret) VALUES (?, ?)`, userID, []byte("encrypted"))
+	execNoErr(t, db, `INSERT INTO user_webauthn_credentials (user_id, name, credential_id, public_key) VALUES (?, ?, ?, ?)`, userID, "key", []byte("cred"), []byte("pub"))
+	execNoErr(t, db, `INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, "abc")
+	execNoErr(t, db, `INSERT INTO mfa_challenges (token, user_id, purpose) VALUES (?, ?, ?)`, "tok", userID, "login")
+
+	var attempts int
+	require.NoError(t, db.Get(&attempts, `SELECT attempts FROM mfa_challenges WHERE token = ?`, "tok"))
+	require.Zero(t, attempts)
+
+	// everything is deleted with the user
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	for _, table := range []string{"user_totp_secrets", "user_webauthn_credentials", "user_mfa_recovery_codes", "mfa_challenges"} {
+		var count int
+		require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM `+table))
+		require.Zero(t, count, table)
+	}
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240419083512, Down_20240419083512)
+}
+
+func Up_20240419083512(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE secret_variables (
+	id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name       VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	value      BLOB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_secret_variables_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create secret_variables table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240419083512(tx *sql.Tx) error {
+	return nil
+}
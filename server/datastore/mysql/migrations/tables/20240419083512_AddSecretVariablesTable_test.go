@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240419083512(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO secret_variables (name, value) VALUES (?, ?)`, "API_KEY", []byte("encrypted"))
+
+	// name must be unique
+	_, err := db.Exec(`INSERT INTO secret_variables (name, value) VALUES (?, ?)`, "API_KEY", []byte("other"))
+	require.Error(t, err)
+
+	var value []byte
+	require.NoError(t, db.Get(&value, `SELECT value FROM secret_variables WHERE name = ?`, "API_KEY"))
+	require.Equal(t, []byte("encrypted"), value)
+}
@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240422094217(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES (?, ?, ?, ?)`, "u", "u@example.com", []byte("pwd"), "salt")
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	end := start.Add(time.Hour)
+	execNoErr(t, db, `INSERT INTO host_log_collections (host_id, execution_id, type, channels, start_time, end_time, user_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		1, "abc", "windows_event_log", `["System"]`, start, end, userID)
+
+	// execution id must be unique
+	_, err := db.Exec(`INSERT INTO host_log_collections (host_id, execution_id, type, start_time, end_time) VALUES (?, ?, ?, ?, ?)`,
+		1, "abc", "macos_unified_log", start, end)
+	require.Error(t, err)
+
+	// deleting the user keeps the log collection
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var row struct {
+		UserID *uint  `db:"user_id"`
+		Size   int64  `db:"size"`
+		Type   string `db:"type"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT user_id, size, type FROM host_log_collections WHERE execution_id = ?`, "abc"))
+	require.Nil(t, row.UserID)
+	require.Zero(t, row.Size)
+	require.Equal(t, "windows_event_log", row.Type)
+}
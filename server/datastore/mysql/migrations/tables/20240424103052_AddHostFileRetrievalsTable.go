@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240424103052, Down_20240424103052)
+}
+
+func Up_20240424103052(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE host_file_retrievals (
+	id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	host_id         INT UNSIGNED NOT NULL,
+	execution_id    VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	path            TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	notify_end_user TINYINT(1) NOT NULL DEFAULT 0,
+	user_id         INT UNSIGNED NULL,
+	error           TEXT COLLATE utf8mb4_unicode_ci NULL,
+	data            LONGBLOB NULL,
+	size            BIGINT NOT NULL DEFAULT 0,
+	created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at    TIMESTAMP NULL,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_host_file_retrievals_execution_id (execution_id),
+	KEY idx_host_file_retrievals_host_id_created_at (host_id, created_at),
+	CONSTRAINT fk_host_file_retrievals_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_file_retrievals table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240424103052(tx *sql.Tx) error {
+	return nil
+}
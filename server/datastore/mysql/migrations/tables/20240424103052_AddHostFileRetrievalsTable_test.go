@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240424103052(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES (?, ?, ?, ?)`, "u", "u@example.com", []byte("pwd"), "salt")
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_file_retrievals (host_id, execution_id, path, user_id) VALUES (?, ?, ?, ?)`,
+		1, "abc", "/var/log/system.log", userID)
+
+	// execution id must be unique
+	_, err := db.Exec(`INSERT INTO host_file_retrievals (host_id, execution_id, path) VALUES (?, ?, ?)`,
+		1, "abc", "/etc/hosts")
+	require.Error(t, err)
+
+	// deleting the user keeps the file retrieval
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var row struct {
+		UserID        *uint  `db:"user_id"`
+		Size          int64  `db:"size"`
+		Path          string `db:"path"`
+		NotifyEndUser bool   `db:"notify_end_user"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT user_id, size, path, notify_end_user FROM host_file_retrievals WHERE execution_id = ?`, "abc"))
+	require.Nil(t, row.UserID)
+	require.Zero(t, row.Size)
+	require.False(t, row.NotifyEndUser)
+	require.Equal(t, "/var/log/system.log", row.Path)
+}
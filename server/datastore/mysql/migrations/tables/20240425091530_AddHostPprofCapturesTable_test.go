@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240425091530(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES (?, ?, ?, ?)`, "u", "u@example.com", []byte("pwd"), "salt")
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_pprof_captures (host_id, execution_id, type, duration_seconds, user_id) VALUES (?, ?, ?, ?, ?)`,
+		1, "abc", "cpu", 30, userID)
+
+	// execution id must be unique
+	_, err := db.Exec(`INSERT INTO host_pprof_captures (host_id, execution_id, type) VALUES (?, ?, ?)`,
+		1, "abc", "heap")
+	require.Error(t, err)
+
+	// deleting the user keeps the pprof capture
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var row struct {
+		UserID          *uint  `db:"user_id"`
+		Size            int64  `db:"size"`
+		Type            string `db:"type"`
+		DurationSeconds uint   `db:"duration_seconds"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT user_id, size, type, duration_seconds FROM host_pprof_captures WHERE execution_id = ?`, "abc"))
+	require.Nil(t, row.UserID)
+	require.Zero(t, row.Size)
+	require.Equal(t, "cpu", row.Type)
+	require.Equal(t, uint(30), row.DurationSeconds)
+}
@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240426104512, Down_20240426104512)
+}
+
+func Up_20240426104512(tx *sql.Tx) error {
+	// host_mdm_apple_profile_certificates tracks when the Wi-Fi and VPN
+	// profiles with a SCEP payload were installed on hosts, which is when the
+	// host got its certificate, so that the profiles can be re-delivered before
+	// the certificates expire.
+	_, err := tx.Exec(`
+CREATE TABLE host_mdm_apple_profile_certificates (
+	host_uuid          VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	profile_uuid       VARCHAR(37) COLLATE utf8mb4_unicode_ci NOT NULL,
+	installed_at       TIMESTAMP NOT NULL,
+	renew_command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL,
+	created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_uuid, profile_uuid),
+	KEY idx_host_mdm_apple_profile_certificates_installed_at (installed_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_profile_certificates table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240426104512(tx *sql.Tx) error {
+	return nil
+}
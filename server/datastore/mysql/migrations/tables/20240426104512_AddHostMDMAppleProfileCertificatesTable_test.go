@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240426104512(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	installedAt := time.Now().UTC().Truncate(time.Second)
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_profile_certificates (host_uuid, profile_uuid, installed_at) VALUES (?, ?, ?)`,
+		"host-1", "a-profile", installedAt)
+
+	// only one row per host and profile
+	_, err := db.Exec(`INSERT INTO host_mdm_apple_profile_certificates (host_uuid, profile_uuid, installed_at) VALUES (?, ?, ?)`,
+		"host-1", "a-profile", installedAt)
+	require.Error(t, err)
+
+	var row struct {
+		InstalledAt      time.Time `db:"installed_at"`
+		RenewCommandUUID *string   `db:"renew_command_uuid"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT installed_at, renew_command_uuid FROM host_mdm_apple_profile_certificates WHERE host_uuid = ?`, "host-1"))
+	require.Equal(t, installedAt, row.InstalledAt.UTC())
+	require.Nil(t, row.RenewCommandUUID)
+}
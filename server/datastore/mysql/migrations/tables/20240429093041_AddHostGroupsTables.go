@@ -0,0 +1,48 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240429093041, Down_20240429093041)
+}
+
+func Up_20240429093041(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE host_groups (
+	id          INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name        VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	description VARCHAR(1023) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	author_id   INT UNSIGNED NULL,
+	created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_host_groups_name (name),
+	CONSTRAINT fk_host_groups_author_id FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_groups table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE host_group_members (
+	host_group_id INT UNSIGNED NOT NULL,
+	host_id       INT UNSIGNED NOT NULL,
+	created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_group_id, host_id),
+	KEY idx_host_group_members_host_id (host_id),
+	CONSTRAINT fk_host_group_members_host_group_id FOREIGN KEY (host_group_id) REFERENCES host_groups (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_group_members table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240429093041(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240429093041(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	groupID := execNoErrLastID(t, db, `INSERT INTO host_groups (name) VALUES (?)`, "group1")
+
+	// name must be unique
+	_, err := db.Exec(`INSERT INTO host_groups (name) VALUES (?)`, "group1")
+	require.Error(t, err)
+
+	execNoErr(t, db, `INSERT INTO host_group_members (host_group_id, host_id) VALUES (?, ?), (?, ?)`, groupID, 1, groupID, 2)
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM host_group_members WHERE host_group_id = ?`, groupID))
+	require.Equal(t, 2, count)
+
+	// members are deleted with the group
+	execNoErr(t, db, `DELETE FROM host_groups WHERE id = ?`, groupID)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM host_group_members`))
+	require.Zero(t, count)
+}
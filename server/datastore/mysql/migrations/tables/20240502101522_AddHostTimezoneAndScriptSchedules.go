@@ -0,0 +1,61 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240502101522, Down_20240502101522)
+}
+
+func Up_20240502101522(tx *sql.Tx) error {
+	// timezone is the IANA name of the local timezone of the host, as reported
+	// by fleetd.
+	_, err := tx.Exec(`ALTER TABLE host_orbit_info ADD COLUMN timezone VARCHAR(255) COLLATE utf8mb4_unicode_ci NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add timezone to host_orbit_info: %w", err)
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE script_schedules (
+	id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	script_id  INT UNSIGNED NOT NULL,
+	-- local_time is the time of day (HH:MM) at which the script runs, in the
+	-- local timezone of each host.
+	local_time CHAR(5) COLLATE utf8mb4_unicode_ci NOT NULL,
+	author_id  INT UNSIGNED NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_script_schedules_script_id_local_time (script_id, local_time),
+	CONSTRAINT fk_script_schedules_script_id FOREIGN KEY (script_id) REFERENCES scripts (id) ON DELETE CASCADE,
+	CONSTRAINT fk_script_schedules_author_id FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create script_schedules table: %w", err)
+	}
+
+	// script_schedule_host_runs records the last local date on which a
+	// scheduled script was queued on a host, so that it runs once per day.
+	_, err = tx.Exec(`
+CREATE TABLE script_schedule_host_runs (
+	script_schedule_id INT UNSIGNED NOT NULL,
+	host_id            INT UNSIGNED NOT NULL,
+	last_run_date      DATE NOT NULL,
+	updated_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (script_schedule_id, host_id),
+	KEY idx_script_schedule_host_runs_host_id (host_id),
+	CONSTRAINT fk_script_schedule_host_runs_schedule_id FOREIGN KEY (script_schedule_id) REFERENCES script_schedules (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create script_schedule_host_runs table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240502101522(tx *sql.Tx) error {
+	return nil
+}
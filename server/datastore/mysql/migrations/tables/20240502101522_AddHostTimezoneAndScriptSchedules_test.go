@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240502101522(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO host_orbit_info (host_id, version) VALUES (1, '1.24.0')`)
+	scriptID := execNoErrLastID(t, db, `INSERT INTO scripts (name, global_or_team_id) VALUES ('script.sh', 0)`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var tz *string
+	require.NoError(t, db.Get(&tz, `SELECT timezone FROM host_orbit_info WHERE host_id = 1`))
+	require.Nil(t, tz)
+	execNoErr(t, db, `UPDATE host_orbit_info SET timezone = 'America/New_York' WHERE host_id = 1`)
+
+	scheduleID := execNoErrLastID(t, db, `INSERT INTO script_schedules (script_id, local_time) VALUES (?, '02:00')`, scriptID)
+	// a script can only be scheduled once at a given time
+	_, err := db.Exec(`INSERT INTO script_schedules (script_id, local_time) VALUES (?, '02:00')`, scriptID)
+	require.Error(t, err)
+
+	execNoErr(t, db, `INSERT INTO script_schedule_host_runs (script_schedule_id, host_id, last_run_date) VALUES (?, 1, '2024-05-02')`, scheduleID)
+
+	// schedules and their runs are deleted with the script
+	execNoErr(t, db, `DELETE FROM scripts WHERE id = ?`, scriptID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM script_schedules`))
+	require.Zero(t, count)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM script_schedule_host_runs`))
+	require.Zero(t, count)
+}
@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240503091544, Down_20240503091544)
+}
+
+func Up_20240503091544(tx *sql.Tx) error {
+	// a host is pending enrollment approval as long as it has a row in this
+	// table, approving the host deletes its row.
+	_, err := tx.Exec(`
+CREATE TABLE host_pending_approvals (
+	host_id    INT UNSIGNED NOT NULL,
+	source     VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_pending_approvals table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240503091544(tx *sql.Tx) error {
+	return nil
+}
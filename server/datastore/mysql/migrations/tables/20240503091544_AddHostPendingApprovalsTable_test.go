@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240503091544(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_pending_approvals (host_id, source) VALUES (?, ?)`, 1, "orbit")
+
+	// a host can be pending approval only once
+	_, err := db.Exec(`INSERT INTO host_pending_approvals (host_id, source) VALUES (?, ?)`, 1, "osquery")
+	require.Error(t, err)
+
+	var row struct {
+		Source    string    `db:"source"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT source, created_at FROM host_pending_approvals WHERE host_id = ?`, 1))
+	require.Equal(t, "orbit", row.Source)
+	require.NotZero(t, row.CreatedAt)
+}
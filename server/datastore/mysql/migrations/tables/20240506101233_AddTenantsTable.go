@@ -0,0 +1,78 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240506101233, Down_20240506101233)
+}
+
+func Up_20240506101233(tx *sql.Tx) error {
+	// the tenant's encryption key is itself encrypted with the server private
+	// key, it encrypts the tenant's escrowed secrets (e.g. MDM certificates).
+	_, err := tx.Exec(`
+CREATE TABLE tenants (
+	id             INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name           VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	description    VARCHAR(1023) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	config         JSON DEFAULT NULL,
+	encryption_key BLOB NOT NULL,
+	created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_tenants_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create tenants table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE tenant_mdm_assets (
+	tenant_id  INT UNSIGNED NOT NULL,
+	name       VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	value      LONGBLOB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (tenant_id, name),
+	CONSTRAINT fk_tenant_mdm_assets_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant_mdm_assets table: %w", err)
+	}
+
+	// a tenant cannot be deleted as long as it has teams or users.
+	_, err = tx.Exec(`
+ALTER TABLE teams
+	ADD COLUMN tenant_id INT UNSIGNED DEFAULT NULL,
+	ADD CONSTRAINT fk_teams_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants (id)`)
+	if err != nil {
+		return fmt.Errorf("failed to add tenant_id to teams: %w", err)
+	}
+
+	_, err = tx.Exec(`
+ALTER TABLE users
+	ADD COLUMN tenant_id INT UNSIGNED DEFAULT NULL,
+	ADD CONSTRAINT fk_users_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants (id)`)
+	if err != nil {
+		return fmt.Errorf("failed to add tenant_id to users: %w", err)
+	}
+
+	// the users created from an invite join the tenant of the user that sent
+	// it, the pending invites of a tenant are deleted with it.
+	_, err = tx.Exec(`
+ALTER TABLE invites
+	ADD COLUMN tenant_id INT UNSIGNED DEFAULT NULL,
+	ADD CONSTRAINT fk_invites_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE`)
+	if err != nil {
+		return fmt.Errorf("failed to add tenant_id to invites: %w", err)
+	}
+	return nil
+}
+
+func Down_20240506101233(tx *sql.Tx) error {
+	return nil
+}
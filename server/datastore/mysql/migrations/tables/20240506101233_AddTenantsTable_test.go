@@ -0,0 +1,54 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240506101233(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO teams (name) VALUES ('team1')`)
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u1', 'u1@example.com', 'p', 's')`)
+	execNoErr(t, db, `INSERT INTO invites (invited_by, email, token) VALUES (?, 'a@example.com', 'tok1')`, userID)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	// existing teams and users are not in a tenant
+	var tenantID *uint
+	require.NoError(t, db.Get(&tenantID, `SELECT tenant_id FROM teams WHERE name = 'team1'`))
+	require.Nil(t, tenantID)
+	require.NoError(t, db.Get(&tenantID, `SELECT tenant_id FROM users WHERE email = 'u1@example.com'`))
+	require.Nil(t, tenantID)
+	require.NoError(t, db.Get(&tenantID, `SELECT tenant_id FROM invites WHERE token = 'tok1'`))
+	require.Nil(t, tenantID)
+
+	res, err := db.Exec(`INSERT INTO tenants (name, encryption_key) VALUES ('tenant1', 'key')`)
+	require.NoError(t, err)
+	id, _ := res.LastInsertId()
+
+	// tenant names are unique
+	_, err = db.Exec(`INSERT INTO tenants (name, encryption_key) VALUES ('tenant1', 'key')`)
+	require.Error(t, err)
+
+	execNoErr(t, db, `UPDATE teams SET tenant_id = ? WHERE name = 'team1'`, id)
+	execNoErr(t, db, `INSERT INTO tenant_mdm_assets (tenant_id, name, value) VALUES (?, 'apns_cert', 'cert')`, id)
+	execNoErr(t, db, `INSERT INTO invites (invited_by, email, token, tenant_id) VALUES (?, 'b@example.com', 'tok2', ?)`, userID, id)
+
+	// a tenant with teams cannot be deleted
+	_, err = db.Exec(`DELETE FROM tenants WHERE id = ?`, id)
+	require.Error(t, err)
+
+	execNoErr(t, db, `UPDATE teams SET tenant_id = NULL WHERE name = 'team1'`)
+	execNoErr(t, db, `DELETE FROM tenants WHERE id = ?`, id)
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM tenant_mdm_assets`))
+	require.Zero(t, count)
+
+	// the invites of a tenant are deleted with it
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM invites`))
+	require.Equal(t, 1, count)
+}
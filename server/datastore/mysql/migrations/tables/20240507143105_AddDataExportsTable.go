@@ -0,0 +1,43 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240507143105, Down_20240507143105)
+}
+
+func Up_20240507143105(tx *sql.Tx) error {
+	// the exports generated in the background are stored until they are
+	// deleted by the cleanup cron job.
+	_, err := tx.Exec(`
+CREATE TABLE data_exports (
+	id            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id       INT UNSIGNED NOT NULL,
+	resource      VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+	format        VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	status        VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+	filename      VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	options       JSON NOT NULL,
+	data          LONGBLOB,
+	size          BIGINT NOT NULL DEFAULT 0,
+	error_message TEXT COLLATE utf8mb4_unicode_ci,
+	created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at  TIMESTAMP NULL DEFAULT NULL,
+
+	PRIMARY KEY (id),
+	KEY idx_data_exports_status (status),
+	KEY idx_data_exports_created_at (created_at),
+	CONSTRAINT fk_data_exports_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create data_exports table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240507143105(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240507143105(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	res, err := db.Exec(`INSERT INTO users (name, email, password, salt) VALUES ('u1', 'u1@example.com', 'p', 's')`)
+	require.NoError(t, err)
+	userID, _ := res.LastInsertId()
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO data_exports (user_id, resource, format, filename, options) VALUES (?, 'hosts', 'csv', 'Hosts.csv', '{}')`, userID)
+
+	var status string
+	require.NoError(t, db.Get(&status, `SELECT status FROM data_exports WHERE user_id = ?`, userID))
+	require.Equal(t, "pending", status)
+
+	// the exports of a deleted user are deleted
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM data_exports`))
+	require.Zero(t, count)
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240508120417, Down_20240508120417)
+}
+
+func Up_20240508120417(tx *sql.Tx) error {
+	// seen_time is the last time the host was seen by osquery, the new columns
+	// track the last time it was seen by the other channels.
+	_, err := tx.Exec(`
+ALTER TABLE host_seen_times
+	ADD COLUMN mdm_seen_time TIMESTAMP NULL DEFAULT NULL,
+	ADD COLUMN orbit_seen_time TIMESTAMP NULL DEFAULT NULL,
+	ADD COLUMN desktop_seen_time TIMESTAMP NULL DEFAULT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add channel seen times to host_seen_times: %w", err)
+	}
+	return nil
+}
+
+func Down_20240508120417(tx *sql.Tx) error {
+	return nil
+}
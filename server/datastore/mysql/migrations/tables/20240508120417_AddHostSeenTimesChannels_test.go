@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240508120417(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	seen := time.Now().UTC().Truncate(time.Second)
+	execNoErr(t, db, `INSERT INTO host_seen_times (host_id, seen_time) VALUES (1, ?)`, seen)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var row struct {
+		SeenTime        *time.Time `db:"seen_time"`
+		MDMSeenTime     *time.Time `db:"mdm_seen_time"`
+		OrbitSeenTime   *time.Time `db:"orbit_seen_time"`
+		DesktopSeenTime *time.Time `db:"desktop_seen_time"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT seen_time, mdm_seen_time, orbit_seen_time, desktop_seen_time FROM host_seen_times WHERE host_id = 1`))
+	require.NotNil(t, row.SeenTime)
+	require.Equal(t, seen, row.SeenTime.UTC())
+	require.Nil(t, row.MDMSeenTime)
+	require.Nil(t, row.OrbitSeenTime)
+	require.Nil(t, row.DesktopSeenTime)
+
+	// a host may be seen by a channel before being seen by osquery
+	execNoErr(t, db, `INSERT INTO host_seen_times (host_id, mdm_seen_time) VALUES (2, ?)`, seen)
+	require.NoError(t, db.Get(&row, `SELECT seen_time, mdm_seen_time, orbit_seen_time, desktop_seen_time FROM host_seen_times WHERE host_id = 2`))
+	require.Nil(t, row.SeenTime)
+	require.NotNil(t, row.MDMSeenTime)
+}
@@ -0,0 +1,57 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240509103012, Down_20240509103012)
+}
+
+func Up_20240509103012(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE mdm_profile_redelivery_campaigns (
+	id               INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	profile_uuid     VARCHAR(37) COLLATE utf8mb4_unicode_ci NOT NULL,
+	profile_name     VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	team_id          INT UNSIGNED NULL,
+	hosts_per_minute INT UNSIGNED NOT NULL,
+	status           VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	total_hosts      INT UNSIGNED NOT NULL DEFAULT 0,
+	delivered_hosts  INT UNSIGNED NOT NULL DEFAULT 0,
+	last_batch_at    TIMESTAMP NULL DEFAULT NULL,
+	created_by_id    INT UNSIGNED NULL,
+	created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	KEY idx_mdm_profile_redelivery_campaigns_status (status),
+	KEY idx_mdm_profile_redelivery_campaigns_team_id (team_id),
+	CONSTRAINT fk_mdm_profile_redelivery_campaigns_created_by_id FOREIGN KEY (created_by_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create mdm_profile_redelivery_campaigns table: %w", err)
+	}
+
+	// the hosts targeted by a campaign, delivered_at is set once the profile
+	// has been queued for redelivery to the host.
+	_, err = tx.Exec(`
+CREATE TABLE mdm_profile_redelivery_campaign_hosts (
+	campaign_id  INT UNSIGNED NOT NULL,
+	host_uuid    VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	delivered_at TIMESTAMP NULL DEFAULT NULL,
+
+	PRIMARY KEY (campaign_id, host_uuid),
+	KEY idx_mdm_profile_redelivery_campaign_hosts_delivered_at (campaign_id, delivered_at),
+	CONSTRAINT fk_mdm_profile_redelivery_campaign_hosts_campaign_id FOREIGN KEY (campaign_id) REFERENCES mdm_profile_redelivery_campaigns (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create mdm_profile_redelivery_campaign_hosts table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240509103012(tx *sql.Tx) error {
+	return nil
+}
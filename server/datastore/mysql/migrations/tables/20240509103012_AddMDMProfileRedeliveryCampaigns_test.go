@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240509103012(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	res, err := db.Exec(`INSERT INTO mdm_profile_redelivery_campaigns (profile_uuid, profile_name, hosts_per_minute, status) VALUES (?, ?, ?, ?)`,
+		"a123", "Wi-Fi", 100, "running")
+	require.NoError(t, err)
+	id, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	execNoErr(t, db, `INSERT INTO mdm_profile_redelivery_campaign_hosts (campaign_id, host_uuid) VALUES (?, ?), (?, ?)`, id, "h1", id, "h2")
+
+	// a host is targeted only once by a campaign
+	_, err = db.Exec(`INSERT INTO mdm_profile_redelivery_campaign_hosts (campaign_id, host_uuid) VALUES (?, ?)`, id, "h1")
+	require.Error(t, err)
+
+	// deleting the campaign deletes its hosts
+	execNoErr(t, db, `DELETE FROM mdm_profile_redelivery_campaigns WHERE id = ?`, id)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM mdm_profile_redelivery_campaign_hosts`))
+	require.Zero(t, count)
+}
@@ -0,0 +1,42 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240510093500, Down_20240510093500)
+}
+
+func Up_20240510093500(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE host_certificates (
+	id               INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	host_id          INT UNSIGNED NOT NULL,
+	sha1             CHAR(40) COLLATE utf8mb4_unicode_ci NOT NULL,
+	common_name      VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	subject          VARCHAR(1024) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	issuer           VARCHAR(1024) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	ca               TINYINT(1) NOT NULL DEFAULT 0,
+	self_signed      TINYINT(1) NOT NULL DEFAULT 0,
+	not_valid_before DATETIME NOT NULL,
+	not_valid_after  DATETIME NOT NULL,
+	source           VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_host_certificates_host_id_sha1 (host_id, sha1),
+	KEY idx_host_certificates_sha1 (sha1),
+	KEY idx_host_certificates_not_valid_after (not_valid_after)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_certificates table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240510093500(tx *sql.Tx) error {
+	return nil
+}
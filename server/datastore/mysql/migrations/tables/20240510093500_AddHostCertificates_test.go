@@ -0,0 +1,22 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240510093500(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	const insStmt = `INSERT INTO host_certificates (host_id, sha1, common_name, not_valid_before, not_valid_after) VALUES (?, ?, ?, ?, ?)`
+	execNoErr(t, db, insStmt, 1, "0123456789abcdef0123456789abcdef01234567", "a", "2024-01-01 00:00:00", "2025-01-01 00:00:00")
+	execNoErr(t, db, insStmt, 2, "0123456789abcdef0123456789abcdef01234567", "a", "2024-01-01 00:00:00", "2025-01-01 00:00:00")
+
+	// a certificate is stored only once per host
+	_, err := db.Exec(insStmt, 1, "0123456789abcdef0123456789abcdef01234567", "a", "2024-01-01 00:00:00", "2025-01-01 00:00:00")
+	require.Error(t, err)
+}
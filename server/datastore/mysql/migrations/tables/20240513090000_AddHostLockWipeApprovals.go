@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240513090000, Down_20240513090000)
+}
+
+func Up_20240513090000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE host_lock_wipe_approvals (
+	host_id              INT UNSIGNED NOT NULL,
+	action               VARCHAR(10) COLLATE utf8mb4_unicode_ci NOT NULL,
+	requested_by_user_id INT UNSIGNED NULL,
+	created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id, action),
+	CONSTRAINT fk_host_lock_wipe_approvals_requested_by_user_id
+		FOREIGN KEY (requested_by_user_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_lock_wipe_approvals table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240513090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240513090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'p', 's')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	const insStmt = `INSERT INTO host_lock_wipe_approvals (host_id, action, requested_by_user_id) VALUES (?, ?, ?)`
+	execNoErr(t, db, insStmt, 1, "lock", userID)
+	execNoErr(t, db, insStmt, 1, "wipe", userID)
+
+	// a single request per host and action
+	_, err := db.Exec(insStmt, 1, "lock", userID)
+	require.Error(t, err)
+
+	// deleting the user keeps the request
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM host_lock_wipe_approvals WHERE requested_by_user_id IS NULL`))
+	require.Equal(t, 2, count)
+}
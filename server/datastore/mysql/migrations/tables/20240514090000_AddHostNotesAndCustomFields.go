@@ -0,0 +1,45 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240514090000, Down_20240514090000)
+}
+
+func Up_20240514090000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE host_notes (
+	host_id    INT UNSIGNED NOT NULL,
+	notes      TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_notes table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE host_custom_field_values (
+	host_id    INT UNSIGNED NOT NULL,
+	name       VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	value      VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id, name),
+	INDEX idx_host_custom_field_values_name_value (name, value)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_custom_field_values table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240514090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240514090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_notes (host_id, notes) VALUES (1, 'some notes')`)
+	_, err := db.Exec(`INSERT INTO host_notes (host_id, notes) VALUES (1, 'other notes')`)
+	require.Error(t, err)
+
+	const insStmt = `INSERT INTO host_custom_field_values (host_id, name, value) VALUES (?, ?, ?)`
+	execNoErr(t, db, insStmt, 1, "owner", "alice")
+	execNoErr(t, db, insStmt, 1, "asset_tag", "A-123")
+	execNoErr(t, db, insStmt, 2, "owner", "alice")
+
+	// a single value per host and field
+	_, err = db.Exec(insStmt, 1, "owner", "bob")
+	require.Error(t, err)
+
+	var hostIDs []uint
+	require.NoError(t, db.Select(&hostIDs, `SELECT host_id FROM host_custom_field_values WHERE name = 'owner' AND value = 'alice' ORDER BY host_id`))
+	require.Equal(t, []uint{1, 2}, hostIDs)
+}
@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240515090000, Down_20240515090000)
+}
+
+func Up_20240515090000(tx *sql.Tx) error {
+	// checked_at is the last time the warranty was looked up from the vendor's
+	// API, it is updated even if the lookup did not return any warranty data so
+	// that the host is not looked up again before the refresh interval.
+	_, err := tx.Exec(`
+CREATE TABLE host_warranties (
+	host_id           INT UNSIGNED NOT NULL,
+	vendor            VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	purchase_date     DATE NULL,
+	warranty_end_date DATE NULL,
+	checked_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id),
+	INDEX idx_host_warranties_checked_at (checked_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_warranties table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240515090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240515090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_warranties (host_id, vendor, purchase_date, warranty_end_date) VALUES (1, 'dell', '2022-03-01', '2025-03-01')`)
+	execNoErr(t, db, `INSERT INTO host_warranties (host_id, vendor) VALUES (2, 'apple')`)
+	_, err := db.Exec(`INSERT INTO host_warranties (host_id, vendor) VALUES (1, 'lenovo')`)
+	require.Error(t, err)
+
+	var row struct {
+		PurchaseDate    *time.Time `db:"purchase_date"`
+		WarrantyEndDate *time.Time `db:"warranty_end_date"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT purchase_date, warranty_end_date FROM host_warranties WHERE host_id = 1`))
+	require.NotNil(t, row.PurchaseDate)
+	require.Equal(t, "2022-03-01", row.PurchaseDate.Format("2006-01-02"))
+	require.NotNil(t, row.WarrantyEndDate)
+	require.Equal(t, "2025-03-01", row.WarrantyEndDate.Format("2006-01-02"))
+
+	require.NoError(t, db.Get(&row, `SELECT purchase_date, warranty_end_date FROM host_warranties WHERE host_id = 2`))
+	require.Nil(t, row.PurchaseDate)
+	require.Nil(t, row.WarrantyEndDate)
+}
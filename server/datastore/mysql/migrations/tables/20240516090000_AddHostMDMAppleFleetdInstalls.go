@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240516090000, Down_20240516090000)
+}
+
+func Up_20240516090000(tx *sql.Tx) error {
+	// command_uuid is the latest InstallEnterpriseApplication command sent to
+	// install fleetd on the host, retries is the number of times it was
+	// re-sent after a failure.
+	_, err := tx.Exec(`
+CREATE TABLE host_mdm_apple_fleetd_installs (
+	host_uuid    VARCHAR(127) COLLATE utf8mb4_unicode_ci NOT NULL,
+	command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NOT NULL,
+	retries      TINYINT UNSIGNED NOT NULL DEFAULT 0,
+	created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_uuid),
+	FOREIGN KEY (command_uuid) REFERENCES nano_commands (command_uuid) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_fleetd_installs table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240516090000(tx *sql.Tx) error {
+	return nil
+}
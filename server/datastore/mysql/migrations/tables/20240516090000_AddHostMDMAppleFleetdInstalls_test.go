@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240516090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO nano_commands (command_uuid, request_type, command) VALUES ('command-uuid', 'InstallEnterpriseApplication', '<?xml')`)
+
+	insertStmt := "INSERT INTO host_mdm_apple_fleetd_installs (host_uuid, command_uuid) VALUES (?, ?)"
+	execNoErr(t, db, insertStmt, "host-uuid", "command-uuid")
+
+	_, err := db.Exec(insertStmt, "host-uuid-2", "not-exists")
+	require.ErrorContains(t, err, "Error 1452")
+	_, err = db.Exec(insertStmt, "host-uuid", "command-uuid")
+	require.ErrorContains(t, err, "Error 1062")
+
+	var retries int
+	require.NoError(t, db.Get(&retries, `SELECT retries FROM host_mdm_apple_fleetd_installs WHERE host_uuid = 'host-uuid'`))
+	require.Zero(t, retries)
+
+	// deleting from nano_commands cascades the deletion of this too
+	execNoErr(t, db, "DELETE FROM nano_commands WHERE command_uuid = ?", "command-uuid")
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM host_mdm_apple_fleetd_installs`))
+	require.Zero(t, count)
+}
@@ -0,0 +1,41 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240517090000, Down_20240517090000)
+}
+
+func Up_20240517090000(tx *sql.Tx) error {
+	// team_id is NULL for the versions of the global configuration, profiles
+	// holds the custom configuration profiles of the team at that time and
+	// checksum is the MD5 of the config and profiles, used to avoid recording a
+	// version when nothing changed.
+	_, err := tx.Exec(`
+CREATE TABLE config_versions (
+	id          INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	team_id     INT UNSIGNED NULL,
+	author_id   INT UNSIGNED NULL,
+	author_name VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	config      JSON NOT NULL,
+	profiles    JSON NOT NULL,
+	checksum    BINARY(16) NOT NULL,
+	created_at  TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+
+	PRIMARY KEY (id),
+	KEY idx_config_versions_team_id_id (team_id, id),
+	FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE,
+	FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create config_versions table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240517090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240517090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	teamID := execNoErrLastID(t, db, `INSERT INTO teams (name) VALUES ('team1')`)
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('user1', 'user1@example.com', 'p', 's')`)
+
+	insertStmt := `INSERT INTO config_versions (team_id, author_id, author_name, config, profiles, checksum) VALUES (?, ?, 'user1', '{}', '[]', UNHEX(MD5('x')))`
+	execNoErr(t, db, insertStmt, nil, userID)
+	execNoErr(t, db, insertStmt, teamID, userID)
+
+	_, err := db.Exec(insertStmt, teamID+1, userID)
+	require.ErrorContains(t, err, "Error 1452")
+
+	// deleting the user keeps the versions
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM config_versions WHERE author_id IS NULL AND author_name = 'user1'`))
+	require.Equal(t, 2, count)
+
+	// deleting the team deletes its versions
+	execNoErr(t, db, `DELETE FROM teams WHERE id = ?`, teamID)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM config_versions`))
+	require.Equal(t, 1, count)
+}
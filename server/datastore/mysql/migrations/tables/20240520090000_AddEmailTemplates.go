@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240520090000, Down_20240520090000)
+}
+
+func Up_20240520090000(tx *sql.Tx) error {
+	// a row exists only for the emails that have been customized, empty
+	// columns fall back to the compiled-in defaults.
+	_, err := tx.Exec(`
+CREATE TABLE email_templates (
+	name           VARCHAR(63) COLLATE utf8mb4_unicode_ci NOT NULL,
+	subject        VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	body           MEDIUMTEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	sender_name    VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	sender_address VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	created_at     TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at     TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+
+	PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create email_templates table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240520090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240520090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO email_templates (name, body) VALUES ('invite', '{{.Token}}')`)
+	_, err := db.Exec(`INSERT INTO email_templates (name, body) VALUES ('invite', '')`)
+	require.ErrorContains(t, err, "Error 1062")
+
+	var tmpl struct {
+		Subject       string `db:"subject"`
+		Body          string `db:"body"`
+		SenderAddress string `db:"sender_address"`
+	}
+	require.NoError(t, db.Get(&tmpl, `SELECT subject, body, sender_address FROM email_templates WHERE name = 'invite'`))
+	require.Empty(t, tmpl.Subject)
+	require.Equal(t, "{{.Token}}", tmpl.Body)
+	require.Empty(t, tmpl.SenderAddress)
+}
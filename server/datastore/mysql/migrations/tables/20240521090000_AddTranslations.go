@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240521090000, Down_20240521090000)
+}
+
+func Up_20240521090000(tx *sql.Tx) error {
+	// a row exists only for the locales that have translations, the messages
+	// that are not translated fall back to the compiled-in defaults.
+	_, err := tx.Exec(`
+CREATE TABLE translations (
+	locale     VARCHAR(35) COLLATE utf8mb4_unicode_ci NOT NULL,
+	messages   JSON NOT NULL,
+	created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+
+	PRIMARY KEY (locale)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create translations table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240521090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240521090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO translations (locale, messages) VALUES ('fr', '{"desktop.my_device": "Mon appareil"}')`)
+	_, err := db.Exec(`INSERT INTO translations (locale, messages) VALUES ('fr', '{}')`)
+	require.ErrorContains(t, err, "Error 1062")
+
+	var msg string
+	require.NoError(t, db.Get(&msg, `SELECT messages->>'$."desktop.my_device"' FROM translations WHERE locale = 'fr'`))
+	require.Equal(t, "Mon appareil", msg)
+}
@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240522090000, Down_20240522090000)
+}
+
+func Up_20240522090000(tx *sql.Tx) error {
+	// post_processing is NULL for the campaigns that stream the raw rows of
+	// the results.
+	_, err := tx.Exec(`
+ALTER TABLE distributed_query_campaigns
+	ADD COLUMN post_processing JSON NULL DEFAULT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add post_processing to distributed_query_campaigns: %w", err)
+	}
+	return nil
+}
+
+func Down_20240522090000(tx *sql.Tx) error {
+	return nil
+}
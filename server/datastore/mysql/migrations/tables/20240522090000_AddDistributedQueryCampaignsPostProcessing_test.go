@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240522090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO distributed_query_campaigns (query_id, status, user_id) VALUES (1, 0, 1)`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var pp sql.NullString
+	require.NoError(t, db.Get(&pp, `SELECT post_processing FROM distributed_query_campaigns WHERE query_id = 1`))
+	require.False(t, pp.Valid)
+
+	execNoErr(t, db, `INSERT INTO distributed_query_campaigns (query_id, status, user_id, post_processing) VALUES (2, 0, 1, '{"function": "count"}')`)
+	var fn string
+	require.NoError(t, db.Get(&fn, `SELECT post_processing->>'$.function' FROM distributed_query_campaigns WHERE query_id = 2`))
+	require.Equal(t, "count", fn)
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240523090000, Down_20240523090000)
+}
+
+func Up_20240523090000(tx *sql.Tx) error {
+	// labels is the list of names of the manual labels that the hosts are
+	// added to when they enroll with the secret, and custom_fields the values
+	// of the host custom fields that are set at enrollment.
+	_, err := tx.Exec(`
+ALTER TABLE enroll_secrets
+	ADD COLUMN labels JSON NULL DEFAULT NULL,
+	ADD COLUMN custom_fields JSON NULL DEFAULT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add labels and custom_fields to enroll_secrets: %w", err)
+	}
+	return nil
+}
+
+func Down_20240523090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package tables
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240523090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO enroll_secrets (secret) VALUES ('abc')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var row struct {
+		Labels       sql.NullString `db:"labels"`
+		CustomFields sql.NullString `db:"custom_fields"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT labels, custom_fields FROM enroll_secrets WHERE secret = 'abc'`))
+	require.False(t, row.Labels.Valid)
+	require.False(t, row.CustomFields.Valid)
+
+	execNoErr(t, db, `INSERT INTO enroll_secrets (secret, labels, custom_fields) VALUES ('def', '["kiosk"]', '{"role": "server"}')`)
+	var role string
+	require.NoError(t, db.Get(&role, `SELECT custom_fields->>'$.role' FROM enroll_secrets WHERE secret = 'def'`))
+	require.Equal(t, "server", role)
+}
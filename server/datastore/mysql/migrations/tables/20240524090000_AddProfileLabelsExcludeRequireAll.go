@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240524090000, Down_20240524090000)
+}
+
+func Up_20240524090000(tx *sql.Tx) error {
+	// exclude is set for the labels that the hosts must not be a member of to
+	// receive the profile, and require_all is set for the labels that the
+	// hosts must all be members of (as opposed to any of them). The existing
+	// labels keep their "member of all the labels" behavior.
+	for _, table := range []string{"mdm_configuration_profile_labels", "mdm_declaration_labels"} {
+		_, err := tx.Exec(fmt.Sprintf(`
+ALTER TABLE %s
+	ADD COLUMN exclude TINYINT(1) NOT NULL DEFAULT 0,
+	ADD COLUMN require_all TINYINT(1) NOT NULL DEFAULT 1`, table))
+		if err != nil {
+			return fmt.Errorf("failed to add exclude and require_all to %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func Down_20240524090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240524090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO labels (id, name, query) VALUES (1, 'l1', 'SELECT 1')`)
+	execNoErr(t, db, `INSERT INTO mdm_apple_configuration_profiles (profile_uuid, team_id, identifier, name, mobileconfig, checksum) VALUES ('a1', 0, 'i1', 'n1', '<plist></plist>', '')`)
+	execNoErr(t, db, `INSERT INTO mdm_configuration_profile_labels (apple_profile_uuid, label_id, label_name) VALUES ('a1', 1, 'l1')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var row struct {
+		Exclude    bool `db:"exclude"`
+		RequireAll bool `db:"require_all"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT exclude, require_all FROM mdm_configuration_profile_labels WHERE apple_profile_uuid = 'a1'`))
+	require.False(t, row.Exclude)
+	require.True(t, row.RequireAll)
+}
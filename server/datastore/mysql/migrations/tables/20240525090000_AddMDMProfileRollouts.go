@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240525090000, Down_20240525090000)
+}
+
+func Up_20240525090000(tx *sql.Tx) error {
+	// profile_uuid is the uuid of an Apple or Windows configuration profile, a
+	// profile has at most one rollout.
+	_, err := tx.Exec(`
+CREATE TABLE mdm_profile_rollouts (
+	profile_uuid                 VARCHAR(37) COLLATE utf8mb4_unicode_ci NOT NULL,
+	canary_percentage            TINYINT UNSIGNED NOT NULL,
+	failure_threshold_percentage TINYINT UNSIGNED NOT NULL,
+	timeout_minutes              INT UNSIGNED NOT NULL,
+	status                       VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	reason                       VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	started_at                   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	finished_at                  TIMESTAMP NULL DEFAULT NULL,
+	created_at                   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at                   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (profile_uuid),
+	KEY idx_mdm_profile_rollouts_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create mdm_profile_rollouts table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240525090000(tx *sql.Tx) error {
+	return nil
+}
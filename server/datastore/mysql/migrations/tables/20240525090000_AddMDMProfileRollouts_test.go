@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240525090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO mdm_profile_rollouts (profile_uuid, canary_percentage, failure_threshold_percentage, timeout_minutes, status) VALUES (?, ?, ?, ?, ?)`,
+		"a123", 10, 5, 1440, "canary")
+
+	// a profile has only one rollout
+	_, err := db.Exec(`INSERT INTO mdm_profile_rollouts (profile_uuid, canary_percentage, failure_threshold_percentage, timeout_minutes, status) VALUES (?, ?, ?, ?, ?)`,
+		"a123", 20, 5, 1440, "canary")
+	require.Error(t, err)
+
+	var rollout struct {
+		Status     string  `db:"status"`
+		Reason     string  `db:"reason"`
+		FinishedAt *string `db:"finished_at"`
+	}
+	require.NoError(t, db.Get(&rollout, `SELECT status, reason, finished_at FROM mdm_profile_rollouts WHERE profile_uuid = ?`, "a123"))
+	require.Equal(t, "canary", rollout.Status)
+	require.Empty(t, rollout.Reason)
+	require.Nil(t, rollout.FinishedAt)
+}
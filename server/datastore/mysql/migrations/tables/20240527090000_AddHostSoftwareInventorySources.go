@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240527090000, Down_20240527090000)
+}
+
+func Up_20240527090000(tx *sql.Tx) error {
+	// source is the channel that reported the software of the host (osquery,
+	// mdm or installer), reported_version is the version reported by that
+	// source when it differs from the version of the software (i.e. when a
+	// source of higher precedence reported another version of it).
+	_, err := tx.Exec(`
+CREATE TABLE host_software_inventory_sources (
+	host_id          INT UNSIGNED NOT NULL,
+	software_id      BIGINT UNSIGNED NOT NULL,
+	source           VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+	reported_version VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id, software_id, source)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_software_inventory_sources table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240527090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240527090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_software_inventory_sources (host_id, software_id, source) VALUES (?, ?, ?)`, 1, 2, "osquery")
+	execNoErr(t, db, `INSERT INTO host_software_inventory_sources (host_id, software_id, source, reported_version) VALUES (?, ?, ?, ?)`, 1, 2, "mdm", "1.0")
+
+	// a source reports a software of a host only once
+	_, err := db.Exec(`INSERT INTO host_software_inventory_sources (host_id, software_id, source) VALUES (?, ?, ?)`, 1, 2, "osquery")
+	require.Error(t, err)
+
+	var versions []string
+	require.NoError(t, db.Select(&versions, `SELECT reported_version FROM host_software_inventory_sources WHERE host_id = ? ORDER BY source`, 1))
+	require.Equal(t, []string{"1.0", ""}, versions)
+}
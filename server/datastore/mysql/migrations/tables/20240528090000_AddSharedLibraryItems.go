@@ -0,0 +1,49 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240528090000, Down_20240528090000)
+}
+
+func Up_20240528090000(tx *sql.Tx) error {
+	// the scripts and profiles of "no team" can be shared with all teams by a
+	// global admin, i.e. they are part of the library of every team.
+	for _, table := range []string{"scripts", "mdm_apple_configuration_profiles", "mdm_windows_configuration_profiles"} {
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN shared_with_all_teams TINYINT(1) NOT NULL DEFAULT 0`, table)); err != nil {
+			return fmt.Errorf("failed to add shared_with_all_teams to %s: %w", table, err)
+		}
+	}
+
+	// the teams that opted out of a shared script or profile, exactly one of
+	// script_id, apple_profile_uuid and windows_profile_uuid is set.
+	_, err := tx.Exec(`
+CREATE TABLE team_shared_library_opt_outs (
+	id                   INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	team_id              INT UNSIGNED NOT NULL,
+	script_id            INT UNSIGNED DEFAULT NULL,
+	apple_profile_uuid   VARCHAR(37) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+	windows_profile_uuid VARCHAR(37) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+	created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_team_shared_library_opt_outs_script (team_id, script_id),
+	UNIQUE KEY idx_team_shared_library_opt_outs_apple_profile (team_id, apple_profile_uuid),
+	UNIQUE KEY idx_team_shared_library_opt_outs_windows_profile (team_id, windows_profile_uuid),
+	FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE,
+	FOREIGN KEY (script_id) REFERENCES scripts (id) ON DELETE CASCADE,
+	FOREIGN KEY (apple_profile_uuid) REFERENCES mdm_apple_configuration_profiles (profile_uuid) ON DELETE CASCADE,
+	FOREIGN KEY (windows_profile_uuid) REFERENCES mdm_windows_configuration_profiles (profile_uuid) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create team_shared_library_opt_outs table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240528090000(tx *sql.Tx) error {
+	return nil
+}
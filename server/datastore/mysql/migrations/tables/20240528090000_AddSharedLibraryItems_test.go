@@ -0,0 +1,44 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240528090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	teamID := execNoErrLastID(t, db, `INSERT INTO teams (name) VALUES ('team1')`)
+	scriptID := execNoErrLastID(t, db, `INSERT INTO scripts (name) VALUES ('script.sh')`)
+	execNoErr(t, db, `INSERT INTO mdm_apple_configuration_profiles (profile_uuid, team_id, identifier, name, mobileconfig, checksum) VALUES ('a1', 0, 'ident', 'name', '<plist></plist>', 'checksum')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	// existing scripts and profiles are not shared
+	var shared bool
+	require.NoError(t, db.Get(&shared, `SELECT shared_with_all_teams FROM scripts WHERE id = ?`, scriptID))
+	require.False(t, shared)
+	require.NoError(t, db.Get(&shared, `SELECT shared_with_all_teams FROM mdm_apple_configuration_profiles WHERE profile_uuid = 'a1'`))
+	require.False(t, shared)
+
+	execNoErr(t, db, `UPDATE scripts SET shared_with_all_teams = 1 WHERE id = ?`, scriptID)
+	execNoErr(t, db, `INSERT INTO team_shared_library_opt_outs (team_id, script_id) VALUES (?, ?)`, teamID, scriptID)
+	execNoErr(t, db, `INSERT INTO team_shared_library_opt_outs (team_id, apple_profile_uuid) VALUES (?, 'a1')`, teamID)
+
+	// a team opts out of an item only once
+	_, err := db.Exec(`INSERT INTO team_shared_library_opt_outs (team_id, script_id) VALUES (?, ?)`, teamID, scriptID)
+	require.Error(t, err)
+
+	// the opt-outs are deleted with the item
+	execNoErr(t, db, `DELETE FROM scripts WHERE id = ?`, scriptID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM team_shared_library_opt_outs WHERE team_id = ?`, teamID))
+	require.Equal(t, 1, count)
+
+	// and with the team
+	execNoErr(t, db, `DELETE FROM teams WHERE id = ?`, teamID)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM team_shared_library_opt_outs`))
+	require.Zero(t, count)
+}
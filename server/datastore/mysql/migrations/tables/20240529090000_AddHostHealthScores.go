@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240529090000, Down_20240529090000)
+}
+
+func Up_20240529090000(tx *sql.Tx) error {
+	// the penalties are the points deducted from 100 for each of the signals
+	// of the host, stored to explain the score. The score is indexed to sort
+	// and filter the hosts by health score.
+	_, err := tx.Exec(`
+CREATE TABLE host_health_scores (
+	host_id                  INT UNSIGNED NOT NULL,
+	score                    TINYINT UNSIGNED NOT NULL,
+	failing_policies_penalty TINYINT UNSIGNED NOT NULL DEFAULT 0,
+	vulnerabilities_penalty  TINYINT UNSIGNED NOT NULL DEFAULT 0,
+	agent_penalty            TINYINT UNSIGNED NOT NULL DEFAULT 0,
+	mdm_penalty              TINYINT UNSIGNED NOT NULL DEFAULT 0,
+	created_at               TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at               TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id),
+	INDEX idx_host_health_scores_score (score)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_health_scores table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240529090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240529090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_health_scores (host_id, score, failing_policies_penalty, agent_penalty) VALUES (1, 70, 15, 15)`)
+	execNoErr(t, db, `INSERT INTO host_health_scores (host_id, score) VALUES (2, 100)`)
+	_, err := db.Exec(`INSERT INTO host_health_scores (host_id, score) VALUES (1, 50)`)
+	require.Error(t, err)
+
+	var row struct {
+		Score                  int `db:"score"`
+		FailingPoliciesPenalty int `db:"failing_policies_penalty"`
+		VulnerabilitiesPenalty int `db:"vulnerabilities_penalty"`
+		AgentPenalty           int `db:"agent_penalty"`
+		MDMPenalty             int `db:"mdm_penalty"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT score, failing_policies_penalty, vulnerabilities_penalty, agent_penalty, mdm_penalty FROM host_health_scores WHERE host_id = 1`))
+	require.Equal(t, 70, row.Score)
+	require.Equal(t, 15, row.FailingPoliciesPenalty)
+	require.Zero(t, row.VulnerabilitiesPenalty)
+	require.Equal(t, 15, row.AgentPenalty)
+	require.Zero(t, row.MDMPenalty)
+}
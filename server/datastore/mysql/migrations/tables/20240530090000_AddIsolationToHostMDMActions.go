@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240530090000, Down_20240530090000)
+}
+
+func Up_20240530090000(tx *sql.Tx) error {
+	// the isolate and unisolate references are the execution IDs of the
+	// scripts that apply and remove the network isolation of the host, the
+	// same way the lock and unlock references are for linux and windows hosts.
+	_, err := tx.Exec(`
+ALTER TABLE host_mdm_actions
+	ADD COLUMN isolate_ref VARCHAR(36) COLLATE utf8mb4_unicode_ci NULL,
+	ADD COLUMN unisolate_ref VARCHAR(36) COLLATE utf8mb4_unicode_ci NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add isolate_ref and unisolate_ref to host_mdm_actions: %w", err)
+	}
+	return nil
+}
+
+func Down_20240530090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240530090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_actions (host_id, lock_ref, fleet_platform) VALUES (1, 'lock', 'linux')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_actions (host_id, isolate_ref, fleet_platform) VALUES (2, 'isolate', 'darwin')`)
+
+	var refs []struct {
+		HostID       uint    `db:"host_id"`
+		LockRef      *string `db:"lock_ref"`
+		IsolateRef   *string `db:"isolate_ref"`
+		UnisolateRef *string `db:"unisolate_ref"`
+	}
+	require.NoError(t, db.Select(&refs, `SELECT host_id, lock_ref, isolate_ref, unisolate_ref FROM host_mdm_actions ORDER BY host_id`))
+	require.Len(t, refs, 2)
+	require.Equal(t, "lock", *refs[0].LockRef)
+	require.Nil(t, refs[0].IsolateRef)
+	require.Nil(t, refs[0].UnisolateRef)
+	require.Nil(t, refs[1].LockRef)
+	require.Equal(t, "isolate", *refs[1].IsolateRef)
+	require.Nil(t, refs[1].UnisolateRef)
+}
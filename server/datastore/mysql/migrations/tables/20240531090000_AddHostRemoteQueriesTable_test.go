@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240531090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES (?, ?, ?, ?)`, "u", "u@example.com", []byte("pwd"), "salt")
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_remote_queries (host_id, execution_id, query, user_id) VALUES (?, ?, ?, ?)`,
+		1, "abc", "SELECT 1", userID)
+
+	// execution id must be unique
+	_, err := db.Exec(`INSERT INTO host_remote_queries (host_id, execution_id, query) VALUES (?, ?, ?)`,
+		1, "abc", "SELECT 2")
+	require.Error(t, err)
+
+	// deleting the user keeps the remote query
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var row struct {
+		UserID   *uint   `db:"user_id"`
+		RowCount int     `db:"row_count"`
+		Query    string  `db:"query"`
+		Results  *string `db:"results"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT user_id, row_count, query, results FROM host_remote_queries WHERE execution_id = ?`, "abc"))
+	require.Nil(t, row.UserID)
+	require.Zero(t, row.RowCount)
+	require.Equal(t, "SELECT 1", row.Query)
+	require.Nil(t, row.Results)
+}
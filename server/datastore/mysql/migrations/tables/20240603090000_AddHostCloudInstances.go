@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240603090000, Down_20240603090000)
+}
+
+func Up_20240603090000(tx *sql.Tx) error {
+	// the provider, region and account are indexed to filter the hosts by
+	// cloud instance.
+	_, err := tx.Exec(`
+CREATE TABLE host_cloud_instances (
+	host_id     INT UNSIGNED NOT NULL,
+	provider    VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	instance_id VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	region      VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	account_id  VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	tags        JSON NOT NULL,
+	created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id),
+	INDEX idx_host_cloud_instances_provider_region (provider, region),
+	INDEX idx_host_cloud_instances_account_id (account_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_cloud_instances table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240603090000(tx *sql.Tx) error {
+	return nil
+}
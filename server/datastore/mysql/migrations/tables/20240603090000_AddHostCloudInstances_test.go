@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240603090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_cloud_instances (host_id, provider, instance_id, region, account_id, tags) VALUES (1, 'aws', 'i-0abc', 'us-east-1', '123456789012', '{"env": "prod"}')`)
+	execNoErr(t, db, `INSERT INTO host_cloud_instances (host_id, provider, instance_id, tags) VALUES (2, 'gcp', '8862617829912345678', '{}')`)
+	_, err := db.Exec(`INSERT INTO host_cloud_instances (host_id, provider, instance_id, tags) VALUES (1, 'azure', 'vm', '{}')`)
+	require.Error(t, err)
+
+	var row struct {
+		Region    string `db:"region"`
+		AccountID string `db:"account_id"`
+		Tags      string `db:"tags"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT region, account_id, tags FROM host_cloud_instances WHERE host_id = 2`))
+	require.Empty(t, row.Region)
+	require.Empty(t, row.AccountID)
+	require.JSONEq(t, `{}`, row.Tags)
+}
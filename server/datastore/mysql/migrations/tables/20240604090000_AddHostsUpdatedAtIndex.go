@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240604090000, Down_20240604090000)
+}
+
+func Up_20240604090000(tx *sql.Tx) error {
+	// the hosts are streamed by the changes stream in (updated_at, id) order.
+	_, err := tx.Exec(`
+	ALTER TABLE hosts
+		ADD INDEX idx_hosts_updated_at_id (updated_at, id)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add index to hosts.updated_at: %w", err)
+	}
+	return nil
+}
+
+func Down_20240604090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240605090000, Down_20240605090000)
+}
+
+func Up_20240605090000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE database_maintenance_runs (
+		name        VARCHAR(255) NOT NULL,
+		last_run_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		duration_ms INT UNSIGNED NOT NULL DEFAULT 0,
+		error       TEXT COLLATE utf8mb4_unicode_ci NULL,
+
+		PRIMARY KEY (name)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create database_maintenance_runs table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE table_size_snapshots (
+		id          BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+		table_name  VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+		table_rows  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		data_bytes  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		index_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		free_bytes  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+		PRIMARY KEY (id),
+		KEY idx_table_size_snapshots_created_at (created_at)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table_size_snapshots table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240605090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240605090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO database_maintenance_runs (name) VALUES ('analyze_tables')`)
+	_, err := db.Exec(`INSERT INTO database_maintenance_runs (name) VALUES ('analyze_tables')`)
+	require.Error(t, err)
+
+	var run struct {
+		LastRunAt  time.Time `db:"last_run_at"`
+		DurationMS uint      `db:"duration_ms"`
+		Error      *string   `db:"error"`
+	}
+	require.NoError(t, db.Get(&run, `SELECT last_run_at, duration_ms, error FROM database_maintenance_runs WHERE name = 'analyze_tables'`))
+	require.False(t, run.LastRunAt.IsZero())
+	require.Zero(t, run.DurationMS)
+	require.Nil(t, run.Error)
+
+	execNoErr(t, db, `INSERT INTO table_size_snapshots (table_name, table_rows, data_bytes, index_bytes) VALUES ('hosts', 10, 16384, 32768)`)
+	var freeBytes uint64
+	require.NoError(t, db.Get(&freeBytes, `SELECT free_bytes FROM table_size_snapshots WHERE table_name = 'hosts'`))
+	require.Zero(t, freeBytes)
+}
@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240606090000, Down_20240606090000)
+}
+
+func Up_20240606090000(tx *sql.Tx) error {
+	// the attributes are NULL if they were not reported by the host, e.g.
+	// secure boot is not reported by Intel Macs without a T2 chip.
+	_, err := tx.Exec(`
+	CREATE TABLE host_mdm_apple_security_info (
+		host_id                     INT UNSIGNED NOT NULL,
+		fde_enabled                 TINYINT(1) NULL,
+		firewall_enabled            TINYINT(1) NULL,
+		firewall_block_all_incoming TINYINT(1) NULL,
+		firewall_stealth_mode       TINYINT(1) NULL,
+		sip_enabled                 TINYINT(1) NULL,
+		secure_boot_level           VARCHAR(32) COLLATE utf8mb4_unicode_ci NULL,
+		remote_desktop_enabled      TINYINT(1) NULL,
+		created_at                  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at                  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+		PRIMARY KEY (host_id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_security_info table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240606090000(tx *sql.Tx) error {
+	return nil
+}
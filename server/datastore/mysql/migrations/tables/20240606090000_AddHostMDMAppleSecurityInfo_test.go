@@ -0,0 +1,30 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240606090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_security_info (host_id, fde_enabled, sip_enabled, secure_boot_level) VALUES (1, 1, 0, 'full')`)
+	_, err := db.Exec(`INSERT INTO host_mdm_apple_security_info (host_id) VALUES (1)`)
+	require.Error(t, err)
+
+	var row struct {
+		FDEEnabled      *bool   `db:"fde_enabled"`
+		SIPEnabled      *bool   `db:"sip_enabled"`
+		FirewallEnabled *bool   `db:"firewall_enabled"`
+		SecureBootLevel *string `db:"secure_boot_level"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT fde_enabled, sip_enabled, firewall_enabled, secure_boot_level FROM host_mdm_apple_security_info WHERE host_id = 1`))
+	require.True(t, *row.FDEEnabled)
+	require.False(t, *row.SIPEnabled)
+	require.Nil(t, row.FirewallEnabled)
+	require.Equal(t, "full", *row.SecureBootLevel)
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240607090000, Down_20240607090000)
+}
+
+func Up_20240607090000(tx *sql.Tx) error {
+	// origin is the source of truth that reported the certificate, so that
+	// the osquery and MDM ingestions only replace their own rows.
+	_, err := tx.Exec(`
+	ALTER TABLE host_certificates
+		ADD COLUMN origin ENUM('osquery', 'mdm') NOT NULL DEFAULT 'osquery' AFTER source,
+		ADD COLUMN is_identity TINYINT(1) NOT NULL DEFAULT 0 AFTER origin`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add origin to host_certificates: %w", err)
+	}
+	return nil
+}
+
+func Down_20240607090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240607090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO host_certificates (host_id, sha1, not_valid_before, not_valid_after) VALUES (1, 'a', NOW(), NOW())`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_certificates (host_id, sha1, not_valid_before, not_valid_after, origin, is_identity) VALUES (1, 'b', NOW(), NOW(), 'mdm', 1)`)
+	_, err := db.Exec(`INSERT INTO host_certificates (host_id, sha1, not_valid_before, not_valid_after, origin) VALUES (1, 'c', NOW(), NOW(), 'other')`)
+	require.Error(t, err)
+
+	var rows []struct {
+		SHA1       string `db:"sha1"`
+		Origin     string `db:"origin"`
+		IsIdentity bool   `db:"is_identity"`
+	}
+	require.NoError(t, db.Select(&rows, `SELECT sha1, origin, is_identity FROM host_certificates ORDER BY sha1`))
+	require.Len(t, rows, 2)
+	require.Equal(t, "osquery", rows[0].Origin)
+	require.False(t, rows[0].IsIdentity)
+	require.Equal(t, "mdm", rows[1].Origin)
+	require.True(t, rows[1].IsIdentity)
+}
@@ -0,0 +1,55 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240610090000, Down_20240610090000)
+}
+
+func Up_20240610090000(tx *sql.Tx) error {
+	// the same event raised again updates the notification with the same
+	// notification_key instead of creating a new one, until the notification
+	// is dismissed.
+	_, err := tx.Exec(`
+CREATE TABLE admin_notifications (
+	id               INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	notification_key VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	type             VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	severity         VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+	title            VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	details          TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	occurrences      INT UNSIGNED NOT NULL DEFAULT 1,
+	created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_admin_notifications_notification_key (notification_key),
+	KEY idx_admin_notifications_updated_at (updated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create admin_notifications table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE admin_notification_reads (
+	notification_id INT UNSIGNED NOT NULL,
+	user_id         INT UNSIGNED NOT NULL,
+	read_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (notification_id, user_id),
+	KEY idx_admin_notification_reads_user_id (user_id),
+	CONSTRAINT fk_admin_notification_reads_notification_id FOREIGN KEY (notification_id) REFERENCES admin_notifications (id) ON DELETE CASCADE,
+	CONSTRAINT fk_admin_notification_reads_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create admin_notification_reads table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240610090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240610090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	res, err := db.Exec(`INSERT INTO users (name, email, password, salt) VALUES ('u1', 'u1@example.com', 'p', 's')`)
+	require.NoError(t, err)
+	userID, _ := res.LastInsertId()
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	res, err = db.Exec(`INSERT INTO admin_notifications (notification_key, type, severity, title, details) VALUES ('k', 'cron_job_failed', 'error', 't', 'd')`)
+	require.NoError(t, err)
+	notifID, _ := res.LastInsertId()
+	_, err = db.Exec(`INSERT INTO admin_notifications (notification_key, type, severity, title, details) VALUES ('k', 'cron_job_failed', 'error', 't', 'd')`)
+	require.Error(t, err)
+
+	execNoErr(t, db, `INSERT INTO admin_notification_reads (notification_id, user_id) VALUES (?, ?)`, notifID, userID)
+
+	// the read state of a deleted user or notification is deleted
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM admin_notification_reads`))
+	require.Zero(t, count)
+
+	res, err = db.Exec(`INSERT INTO users (name, email, password, salt) VALUES ('u2', 'u2@example.com', 'p', 's')`)
+	require.NoError(t, err)
+	userID, _ = res.LastInsertId()
+	execNoErr(t, db, `INSERT INTO admin_notification_reads (notification_id, user_id) VALUES (?, ?)`, notifID, userID)
+	execNoErr(t, db, `DELETE FROM admin_notifications WHERE id = ?`, notifID)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM admin_notification_reads`))
+	require.Zero(t, count)
+}
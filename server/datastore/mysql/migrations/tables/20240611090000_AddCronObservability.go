@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240611090000, Down_20240611090000)
+}
+
+func Up_20240611090000(tx *sql.Tx) error {
+	// errors holds the errors of the jobs that failed during the run, by job
+	// ID.
+	_, err := tx.Exec(`ALTER TABLE cron_stats ADD COLUMN errors JSON NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add errors to cron_stats: %w", err)
+	}
+
+	// a row means that the scheduled runs of the cron schedule are paused on
+	// all the Fleet instances.
+	_, err = tx.Exec(`
+CREATE TABLE cron_schedule_pauses (
+	name       VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create cron_schedule_pauses table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240611090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240611090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO cron_stats (name, instance, stats_type, status) VALUES ('cleanups_then_aggregation', 'a', 'scheduled', 'completed')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	var errors *string
+	require.NoError(t, db.Get(&errors, `SELECT errors FROM cron_stats WHERE name = 'cleanups_then_aggregation'`))
+	require.Nil(t, errors)
+
+	execNoErr(t, db, `UPDATE cron_stats SET errors = '{"cleanup": "boom"}' WHERE name = 'cleanups_then_aggregation'`)
+	require.NoError(t, db.Get(&errors, `SELECT JSON_EXTRACT(errors, '$.cleanup') FROM cron_stats WHERE name = 'cleanups_then_aggregation'`))
+	require.NotNil(t, errors)
+	require.Equal(t, `"boom"`, *errors)
+
+	execNoErr(t, db, `INSERT INTO cron_schedule_pauses (name) VALUES ('vulnerabilities')`)
+	_, err := db.Exec(`INSERT INTO cron_schedule_pauses (name) VALUES ('vulnerabilities')`)
+	require.Error(t, err)
+}
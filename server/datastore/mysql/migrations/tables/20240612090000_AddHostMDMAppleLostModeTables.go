@@ -0,0 +1,63 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240612090000, Down_20240612090000)
+}
+
+func Up_20240612090000(tx *sql.Tx) error {
+	// a row exists while the host is in Lost Mode or a command to enable it is
+	// pending, it is deleted when the host acknowledges the command to disable
+	// it.
+	_, err := tx.Exec(`
+CREATE TABLE host_mdm_apple_lost_mode (
+	host_id              INT UNSIGNED NOT NULL,
+	status               VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+	message              TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	phone_number         VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+	footnote             TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+	enable_command_uuid  VARCHAR(127) COLLATE utf8mb4_unicode_ci NOT NULL,
+	disable_command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL,
+	created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_lost_mode table: %w", err)
+	}
+
+	// the locations reported by the hosts in response to the DeviceLocation
+	// command.
+	_, err = tx.Exec(`
+CREATE TABLE host_mdm_apple_locations (
+	id                  INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	host_id             INT UNSIGNED NOT NULL,
+	command_uuid        VARCHAR(127) COLLATE utf8mb4_unicode_ci NOT NULL,
+	latitude            DOUBLE NOT NULL,
+	longitude           DOUBLE NOT NULL,
+	altitude            DOUBLE NULL,
+	horizontal_accuracy DOUBLE NULL,
+	vertical_accuracy   DOUBLE NULL,
+	speed               DOUBLE NULL,
+	course              DOUBLE NULL,
+	reported_at         TIMESTAMP NULL,
+	created_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_host_mdm_apple_locations_command_host (command_uuid, host_id),
+	KEY idx_host_mdm_apple_locations_host_id_created_at (host_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_locations table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240612090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240612090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_lost_mode (host_id, status, message, footnote, enable_command_uuid) VALUES (1, 'pending_enable', 'lost', '', 'cmd-1')`)
+	_, err := db.Exec(`INSERT INTO host_mdm_apple_lost_mode (host_id, status, message, footnote, enable_command_uuid) VALUES (1, 'pending_enable', 'lost', '', 'cmd-2')`)
+	require.Error(t, err)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_locations (host_id, command_uuid, latitude, longitude) VALUES (1, 'cmd-3', 45.5, -73.5)`)
+	var lat float64
+	require.NoError(t, db.Get(&lat, `SELECT latitude FROM host_mdm_apple_locations WHERE host_id = 1`))
+	require.Equal(t, 45.5, lat)
+}
@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240613090000, Down_20240613090000)
+}
+
+func Up_20240613090000(tx *sql.Tx) error {
+	// the bypass code is encrypted with the server private key, the row is
+	// deleted when the host acknowledges the ClearActivationLockBypassCode
+	// command.
+	_, err := tx.Exec(`
+CREATE TABLE host_mdm_apple_activation_lock_bypass_codes (
+	host_id            INT UNSIGNED NOT NULL,
+	bypass_code        BLOB NOT NULL,
+	clear_command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL,
+	created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_activation_lock_bypass_codes table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240613090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240613090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_activation_lock_bypass_codes (host_id, bypass_code) VALUES (1, 'encrypted')`)
+	_, err := db.Exec(`INSERT INTO host_mdm_apple_activation_lock_bypass_codes (host_id, bypass_code) VALUES (1, 'other')`)
+	require.Error(t, err)
+
+	var clearCmd *string
+	require.NoError(t, db.Get(&clearCmd, `SELECT clear_command_uuid FROM host_mdm_apple_activation_lock_bypass_codes WHERE host_id = 1`))
+	require.Nil(t, clearCmd)
+}
@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240614090000, Down_20240614090000)
+}
+
+func Up_20240614090000(tx *sql.Tx) error {
+	// a client certificate is bound to the first host that enrolled with it,
+	// a host can have multiple certificates (e.g. after a renewal).
+	_, err := tx.Exec(`
+CREATE TABLE host_enrollment_certificates (
+	id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	host_id         INT UNSIGNED NOT NULL,
+	sha256          CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+	serial_number   VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+	subject         VARCHAR(1024) COLLATE utf8mb4_unicode_ci NOT NULL,
+	issuer          VARCHAR(1024) COLLATE utf8mb4_unicode_ci NOT NULL,
+	not_valid_after DATETIME NOT NULL,
+	revoked_at      TIMESTAMP NULL,
+	created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_host_enrollment_certificates_sha256 (sha256),
+	KEY idx_host_enrollment_certificates_host_id (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_enrollment_certificates table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240614090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240614090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	const insertStmt = `INSERT INTO host_enrollment_certificates
+		(host_id, sha256, serial_number, subject, issuer, not_valid_after)
+		VALUES (?, ?, '01', 'CN=host', 'CN=ca', '2030-01-01 00:00:00')`
+	sha := "a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf"
+	execNoErr(t, db, insertStmt, 1, sha)
+	// the same certificate cannot be bound to another host
+	_, err := db.Exec(insertStmt, 2, sha)
+	require.Error(t, err)
+
+	var revokedAt *string
+	require.NoError(t, db.Get(&revokedAt, `SELECT revoked_at FROM host_enrollment_certificates WHERE host_id = 1`))
+	require.Nil(t, revokedAt)
+}
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240617090000, Down_20240617090000)
+}
+
+func Up_20240617090000(tx *sql.Tx) error {
+	// key_slot is the LUKS key slot of the passphrase escrowed by Linux hosts,
+	// it is NULL for the FileVault and BitLocker keys.
+	_, err := tx.Exec(`
+	ALTER TABLE host_disk_encryption_keys
+		ADD COLUMN key_slot TINYINT UNSIGNED NULL AFTER decryptable`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add key_slot to host_disk_encryption_keys: %w", err)
+	}
+	return nil
+}
+
+func Down_20240617090000(tx *sql.Tx) error {
+	return nil
+}
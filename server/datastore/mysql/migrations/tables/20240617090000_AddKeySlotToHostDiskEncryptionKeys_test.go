@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240617090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO host_disk_encryption_keys (host_id, base64_encrypted, decryptable) VALUES (1, 'a', 1)`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_disk_encryption_keys (host_id, base64_encrypted, decryptable, key_slot) VALUES (2, 'b', 1, 1)`)
+
+	var rows []struct {
+		HostID  uint          `db:"host_id"`
+		KeySlot sql.NullInt64 `db:"key_slot"`
+	}
+	require.NoError(t, db.Select(&rows, `SELECT host_id, key_slot FROM host_disk_encryption_keys ORDER BY host_id`))
+	require.Len(t, rows, 2)
+	require.False(t, rows[0].KeySlot.Valid)
+	require.True(t, rows[1].KeySlot.Valid)
+	require.EqualValues(t, 1, rows[1].KeySlot.Int64)
+}
@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240618090000, Down_20240618090000)
+}
+
+func Up_20240618090000(tx *sql.Tx) error {
+	// the passwords are encrypted with the server private key. The pending
+	// password replaces the current one when the host acknowledges the
+	// SetRecoveryLock command.
+	_, err := tx.Exec(`
+CREATE TABLE host_mdm_apple_recovery_lock_passwords (
+	host_id             INT UNSIGNED NOT NULL,
+	password            BLOB NULL,
+	pending_password    BLOB NULL,
+	set_command_uuid    VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL,
+	set_at              TIMESTAMP NULL,
+	error_message       TEXT COLLATE utf8mb4_unicode_ci NULL,
+	verify_command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL,
+	verified            TINYINT(1) NULL,
+	password_updated_at TIMESTAMP NULL,
+	created_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_mdm_apple_recovery_lock_passwords table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240618090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240618090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_mdm_apple_recovery_lock_passwords (host_id, pending_password, set_command_uuid) VALUES (1, 'encrypted', 'cmd-uuid')`)
+	_, err := db.Exec(`INSERT INTO host_mdm_apple_recovery_lock_passwords (host_id) VALUES (1)`)
+	require.Error(t, err)
+
+	var row struct {
+		Password *[]byte `db:"password"`
+		Verified *bool   `db:"verified"`
+	}
+	require.NoError(t, db.Get(&row, `SELECT password, verified FROM host_mdm_apple_recovery_lock_passwords WHERE host_id = 1`))
+	require.Nil(t, row.Password)
+	require.Nil(t, row.Verified)
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240619090000, Down_20240619090000)
+}
+
+func Up_20240619090000(tx *sql.Tx) error {
+	// the state reported by orbit for the Linux hosts whose root volume is not
+	// encrypted while disk encryption is enforced.
+	_, err := tx.Exec(`
+CREATE TABLE host_linux_disk_encryption_enforcement (
+	host_id    INT UNSIGNED NOT NULL,
+	status     VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+	detail     TEXT COLLATE utf8mb4_unicode_ci NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (host_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("failed to create host_linux_disk_encryption_enforcement table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240619090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240619090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_linux_disk_encryption_enforcement (host_id, status) VALUES (1, 'action_required')`)
+	_, err := db.Exec(`INSERT INTO host_linux_disk_encryption_enforcement (host_id, status) VALUES (1, 'failed')`)
+	require.Error(t, err)
+
+	var detail *string
+	require.NoError(t, db.Get(&detail, `SELECT detail FROM host_linux_disk_encryption_enforcement WHERE host_id = 1`))
+	require.Nil(t, detail)
+}
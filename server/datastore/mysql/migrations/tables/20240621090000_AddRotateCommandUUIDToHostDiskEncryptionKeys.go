@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240621090000, Down_20240621090000)
+}
+
+func Up_20240621090000(tx *sql.Tx) error {
+	// rotate_command_uuid is the UUID of the pending RotateFileVaultKey MDM
+	// command sent to rotate the FileVault personal recovery key of the host.
+	_, err := tx.Exec(`
+	ALTER TABLE host_disk_encryption_keys
+		ADD COLUMN rotate_command_uuid VARCHAR(127) COLLATE utf8mb4_unicode_ci NULL AFTER client_error`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add rotate_command_uuid to host_disk_encryption_keys: %w", err)
+	}
+	return nil
+}
+
+func Down_20240621090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240621090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	execNoErr(t, db, `INSERT INTO host_disk_encryption_keys (host_id, base64_encrypted, decryptable) VALUES (1, 'a', 1)`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_disk_encryption_keys (host_id, base64_encrypted, decryptable, rotate_command_uuid) VALUES (2, 'b', 1, 'cmd-uuid')`)
+
+	var rows []struct {
+		HostID            uint           `db:"host_id"`
+		RotateCommandUUID sql.NullString `db:"rotate_command_uuid"`
+	}
+	require.NoError(t, db.Select(&rows, `SELECT host_id, rotate_command_uuid FROM host_disk_encryption_keys ORDER BY host_id`))
+	require.Len(t, rows, 2)
+	require.False(t, rows[0].RotateCommandUUID.Valid)
+	require.Equal(t, "cmd-uuid", rows[1].RotateCommandUUID.String)
+}
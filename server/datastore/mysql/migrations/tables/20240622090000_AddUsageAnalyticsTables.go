@@ -0,0 +1,46 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240622090000, Down_20240622090000)
+}
+
+func Up_20240622090000(tx *sql.Tx) error {
+	// usage_analytics_api_requests stores the number of API requests served per
+	// day and API endpoint, path is the template of the API path (e.g.
+	// "/api/_version_/fleet/hosts/{id}").
+	_, err := tx.Exec(`
+	CREATE TABLE usage_analytics_api_requests (
+		date          DATE NOT NULL,
+		method        VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+		path          VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+		request_count BIGINT UNSIGNED NOT NULL DEFAULT 0,
+
+		PRIMARY KEY (date, method, path)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create usage_analytics_api_requests table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE usage_analytics_host_counts (
+		date      DATE NOT NULL,
+		num_hosts INT UNSIGNED NOT NULL DEFAULT 0,
+
+		PRIMARY KEY (date)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create usage_analytics_host_counts table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240622090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240622090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO usage_analytics_api_requests (date, method, path, request_count) VALUES ('2024-06-22', 'GET', '/api/_version_/fleet/hosts', 3)`)
+	execNoErr(t, db, `INSERT INTO usage_analytics_host_counts (date, num_hosts) VALUES ('2024-06-22', 10)`)
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT request_count FROM usage_analytics_api_requests WHERE date = '2024-06-22' AND method = 'GET'`))
+	require.Equal(t, 3, count)
+	require.NoError(t, db.Get(&count, `SELECT num_hosts FROM usage_analytics_host_counts WHERE date = '2024-06-22'`))
+	require.Equal(t, 10, count)
+}
@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240623090000, Down_20240623090000)
+}
+
+func Up_20240623090000(tx *sql.Tx) error {
+	// policy_compliance_daily_counts stores a daily snapshot of policy_stats,
+	// to report the policy compliance trends. team_id is the team of the hosts
+	// counted (the team of a team policy, or the team that inherits a global
+	// policy) and 0 for the global policies counted on all hosts. There is no
+	// foreign key on the policy so that the history of the team is kept when
+	// the policy is deleted.
+	_, err := tx.Exec(`
+	CREATE TABLE policy_compliance_daily_counts (
+		date               DATE NOT NULL,
+		team_id            INT UNSIGNED NOT NULL DEFAULT 0,
+		policy_id          INT UNSIGNED NOT NULL,
+		passing_host_count MEDIUMINT UNSIGNED NOT NULL DEFAULT 0,
+		failing_host_count MEDIUMINT UNSIGNED NOT NULL DEFAULT 0,
+
+		PRIMARY KEY (date, team_id, policy_id),
+		KEY idx_policy_compliance_daily_counts_team_date (team_id, date)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create policy_compliance_daily_counts table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240623090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240623090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO policy_compliance_daily_counts (date, team_id, policy_id, passing_host_count, failing_host_count) VALUES ('2024-06-23', 0, 1, 8, 2)`)
+	execNoErr(t, db, `INSERT INTO policy_compliance_daily_counts (date, team_id, policy_id, passing_host_count, failing_host_count) VALUES ('2024-06-23', 1, 1, 3, 1)`)
+
+	var counts struct {
+		Passing int `db:"passing_host_count"`
+		Failing int `db:"failing_host_count"`
+	}
+	require.NoError(t, db.Get(&counts, `SELECT passing_host_count, failing_host_count FROM policy_compliance_daily_counts WHERE date = '2024-06-23' AND team_id = 1 AND policy_id = 1`))
+	require.Equal(t, 3, counts.Passing)
+	require.Equal(t, 1, counts.Failing)
+}
@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20240624090000, Down_20240624090000)
+}
+
+func Up_20240624090000(tx *sql.Tx) error {
+	// host_cve_detections stores when a vulnerability (of a software or of the
+	// operating system) was first detected on a host, to track the remediation
+	// SLAs. The rows are added and removed by the vulnerabilities cron job,
+	// sla_breach_notified_at is set once the breach of the SLA is escalated.
+	_, err := tx.Exec(`
+	CREATE TABLE host_cve_detections (
+		host_id                INT UNSIGNED NOT NULL,
+		cve                    VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+		first_detected_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sla_breach_notified_at TIMESTAMP NULL DEFAULT NULL,
+
+		PRIMARY KEY (host_id, cve),
+		KEY idx_host_cve_detections_cve (cve)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create host_cve_detections table: %w", err)
+	}
+	return nil
+}
+
+func Down_20240624090000(tx *sql.Tx) error {
+	return nil
+}
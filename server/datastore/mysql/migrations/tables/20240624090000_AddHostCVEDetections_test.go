@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20240624090000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_cve_detections (host_id, cve) VALUES (1, 'CVE-2024-0001')`)
+	execNoErr(t, db, `INSERT INTO host_cve_detections (host_id, cve, first_detected_at) VALUES (2, 'CVE-2024-0001', '2024-06-01 00:00:00')`)
+
+	var detection struct {
+		FirstDetectedAt     time.Time  `db:"first_detected_at"`
+		SLABreachNotifiedAt *time.Time `db:"sla_breach_notified_at"`
+	}
+	require.NoError(t, db.Get(&detection, `SELECT first_detected_at, sla_breach_notified_at FROM host_cve_detections WHERE host_id = 1 AND cve = 'CVE-2024-0001'`))
+	require.WithinDuration(t, time.Now(), detection.FirstDetectedAt, time.Minute)
+	require.Nil(t, detection.SLABreachNotifiedAt)
+
+	_, err := db.Exec(`INSERT INTO host_cve_detections (host_id, cve) VALUES (1, 'CVE-2024-0001')`)
+	require.Error(t, err)
+}
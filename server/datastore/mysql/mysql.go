@@ -4,6 +4,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VividCortex/mysqlerr"
@@ -38,8 +40,11 @@ import (
 )
 
 const (
-	defaultSelectLimit   = 1000000
-	mySQLTimestampFormat = "2006-01-02 15:04:05" // %Y/%m/%d %H:%M:%S
+	defaultSelectLimit = 1000000
+	// defaultStreamBatchSize is the number of rows loaded per batch by the
+	// Stream* methods when the caller does not specify a batch size.
+	defaultStreamBatchSize = 1000
+	mySQLTimestampFormat   = "2006-01-02 15:04:05" // %Y/%m/%d %H:%M:%S
 )
 
 // Matches all non-word and '-' characters for replacement
@@ -71,6 +76,10 @@ type Datastore struct {
 	// database (see file software.go).
 	minLastOpenedAtDiff time.Duration
 
+	// server private key used to encrypt sensitive data at rest (see file
+	// encryption.go), empty if not configured.
+	serverPrivateKey string
+
 	writeCh chan itemToWrite
 
 	// stmtCacheMu protects access to stmtCache.
@@ -169,12 +178,13 @@ type entity struct {
 }
 
 var (
-	hostsTable    = entity{"hosts"}
-	invitesTable  = entity{"invites"}
-	packsTable    = entity{"packs"}
-	queriesTable  = entity{"queries"}
-	sessionsTable = entity{"sessions"}
-	usersTable    = entity{"users"}
+	apiTokensTable = entity{"api_tokens"}
+	hostsTable     = entity{"hosts"}
+	invitesTable   = entity{"invites"}
+	packsTable     = entity{"packs"}
+	queriesTable   = entity{"queries"}
+	sessionsTable  = entity{"sessions"}
+	usersTable     = entity{"users"}
 )
 
 var doRetryErr = errors.New("fleet datastore retry")
@@ -335,6 +345,7 @@ func New(config config.MysqlConfig, c clock.Clock, opts ...DBOption) (*Datastore
 		writeCh:             make(chan itemToWrite),
 		stmtCache:           make(map[string]*sqlx.Stmt),
 		minLastOpenedAtDiff: options.minLastOpenedAtDiff,
+		serverPrivateKey:    options.privateKey,
 	}
 
 	go ds.writeChanLoop()
@@ -372,7 +383,10 @@ func (ds *Datastore) writeChanLoop() {
 	}
 }
 
-var otelTracedDriverName string
+var (
+	otelTracedDriverName string
+	interceptedDrivers   atomic.Int64
+)
 
 func init() {
 	var err error
@@ -392,7 +406,9 @@ func newDB(conf *config.MysqlConfig, opts *dbOptions) (*sqlx.DB, error) {
 		}
 	}
 	if opts.interceptor != nil {
-		driverName = "mysql-mw"
+		// the driver is registered once per database (primary and replica) and
+		// the driver names must be unique.
+		driverName = fmt.Sprintf("mysql-mw-%d", interceptedDrivers.Add(1))
 		sql.Register(driverName, sqlmw.Driver(mysql.MySQLDriver{}, opts.interceptor))
 	}
 	if opts.sqlMode != "" {
@@ -821,6 +837,16 @@ func appendListOptionsWithCursorToSQL(sql string, params []interface{}, opts *fl
 // filter provides the filtering parameters that should be used. hostKey is the
 // name/alias of the hosts table to use in generating the SQL.
 func (ds *Datastore) whereFilterHostsByTeams(filter fleet.TeamFilter, hostKey string) string {
+	clause := ds.whereFilterHostsByUserTeams(filter, hostKey)
+	if filter.User == nil || filter.User.TenantID == nil || clause == "FALSE" {
+		return clause
+	}
+	// the users of a tenant only see the hosts of the tenant's teams, even
+	// with a global role.
+	return fmt.Sprintf("(%s AND %s.team_id IN (SELECT id FROM teams WHERE tenant_id = %d))", clause, hostKey, *filter.User.TenantID)
+}
+
+func (ds *Datastore) whereFilterHostsByUserTeams(filter fleet.TeamFilter, hostKey string) string {
 	if filter.User == nil {
 		// This is likely unintentional, however we would like to return no
 		// results rather than panicking or returning some other error. At least
@@ -961,6 +987,16 @@ func (ds *Datastore) whereFilterGlobalOrTeamIDByTeamsWithSqlFilter(
 // filter provides the filtering parameters that should be used. teamKey is the
 // name/alias of the teams table to use in generating the SQL.
 func (ds *Datastore) whereFilterTeams(filter fleet.TeamFilter, teamKey string) string {
+	clause := ds.whereFilterUserTeams(filter, teamKey)
+	if filter.User == nil || filter.User.TenantID == nil || clause == "FALSE" {
+		return clause
+	}
+	// the users of a tenant only see the tenant's teams, even with a global
+	// role.
+	return fmt.Sprintf("(%s AND %s.tenant_id = %d)", clause, teamKey, *filter.User.TenantID)
+}
+
+func (ds *Datastore) whereFilterUserTeams(filter fleet.TeamFilter, teamKey string) string {
 	if filter.User == nil {
 		// This is likely unintentional, however we would like to return no
 		// results rather than panicking or returning some other error. At least
@@ -1200,6 +1236,17 @@ func (ds *Datastore) ProcessList(ctx context.Context) ([]fleet.MySQLProcess, err
 	return processList, nil
 }
 
+// ExplainQuery returns the execution plan of the SELECT query, as returned by
+// EXPLAIN FORMAT=JSON. It is used to report the slow queries of the requests
+// exceeding their latency budget.
+func (ds *Datastore) ExplainQuery(ctx context.Context, query string, args ...interface{}) (json.RawMessage, error) {
+	var plan string
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &plan, "EXPLAIN FORMAT=JSON "+query, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "explain query")
+	}
+	return json.RawMessage(plan), nil
+}
+
 func insertOnDuplicateDidInsert(res sql.Result) bool {
 	// Note that connection string sets CLIENT_FOUND_ROWS (see
 	// generateMysqlConnectionString in this package), so LastInsertId is 0
@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -590,6 +591,28 @@ func TestWhereFilterHostsByTeams(t *testing.T) {
 			},
 			expected: "hosts.team_id = 2",
 		},
+		// Tenant users only see the hosts of the tenant's teams
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(5)},
+			},
+			expected: "(TRUE AND hosts.team_id IN (SELECT id FROM teams WHERE tenant_id = 5))",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{
+					Teams:    []fleet.UserTeam{{Role: fleet.RoleMaintainer, Team: fleet.Team{ID: 2}}},
+					TenantID: ptr.Uint(5),
+				},
+			},
+			expected: "(hosts.team_id IN (2) AND hosts.team_id IN (SELECT id FROM teams WHERE tenant_id = 5))",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver), TenantID: ptr.Uint(5)},
+			},
+			expected: "FALSE",
+		},
 	}
 
 	for _, tt := range testCases {
@@ -851,6 +874,19 @@ func TestWhereFilterTeams(t *testing.T) {
 			},
 			expected: "t.id IN (1)",
 		},
+		// Tenant users only see the tenant's teams
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(5)},
+			},
+			expected: "(TRUE AND t.tenant_id = 5)",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}, TenantID: ptr.Uint(5)},
+			},
+			expected: "(t.id IN (1) AND t.tenant_id = 5)",
+		},
 	}
 
 	for _, tt := range testCases {
@@ -969,6 +1005,11 @@ func TestDebugs(t *testing.T) {
 	processList, err := ds.ProcessList(context.Background())
 	require.NoError(t, err)
 	require.Greater(t, len(processList), 0)
+
+	plan, err := ds.ExplainQuery(context.Background(), "SELECT id FROM hosts WHERE id = ?", 1)
+	require.NoError(t, err)
+	require.True(t, json.Valid(plan))
+	require.Contains(t, string(plan), "query_block")
 }
 
 func TestWantedModesEnabled(t *testing.T) {
@@ -4,15 +4,18 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	nanodep_client "github.com/fleetdm/fleet/v4/server/mdm/nanodep/client"
 	nanodep_mysql "github.com/fleetdm/fleet/v4/server/mdm/nanodep/storage/mysql"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/cryptoutil"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
 	nanomdm_mysql "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/storage/mysql"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -24,6 +27,10 @@ type NanoMDMStorage struct {
 	logger      log.Logger
 	pushCertPEM []byte
 	pushKeyPEM  []byte
+	// serverPrivateKey decrypts the APNs certificates of the tenants and
+	// encrypts the secrets escrowed with the commands, e.g. the firmware
+	// passwords.
+	serverPrivateKey string
 }
 
 // NewMDMAppleMDMStorage returns a MySQL nanomdm storage that uses the Datastore
@@ -34,21 +41,29 @@ func (ds *Datastore) NewMDMAppleMDMStorage(pushCertPEM []byte, pushKeyPEM []byte
 		return nil, err
 	}
 	return &NanoMDMStorage{
-		MySQLStorage: s,
-		pushCertPEM:  pushCertPEM,
-		pushKeyPEM:   pushKeyPEM,
-		db:           ds.primary,
-		logger:       ds.logger,
+		MySQLStorage:     s,
+		pushCertPEM:      pushCertPEM,
+		pushKeyPEM:       pushKeyPEM,
+		db:               ds.primary,
+		logger:           ds.logger,
+		serverPrivateKey: ds.serverPrivateKey,
 	}, nil
 }
 
 // RetrievePushCert partially implements nanomdm_storage.PushCertStore.
 //
-// Always returns "0" as stale token because we are not storing the APNS in MySQL storage,
-// and instead loading them at startup, thus the APNS will never be considered stale.
+// The hosts of a tenant with its own APNs certificate are enrolled with the
+// topic of that certificate, so it is returned if a tenant's certificate
+// matches the topic. Otherwise, the APNs certificate loaded at startup is
+// returned with "0" as stale token, as it will never be considered stale.
 func (s *NanoMDMStorage) RetrievePushCert(
 	ctx context.Context, topic string,
 ) (cert *tls.Certificate, staleToken string, err error) {
+	cert, staleToken, err = s.retrieveTenantPushCert(ctx, topic)
+	if err != nil || cert != nil {
+		return cert, staleToken, err
+	}
+
 	tlsCert, err := tls.X509KeyPair(s.pushCertPEM, s.pushKeyPEM)
 	if err != nil {
 		return nil, "", err
@@ -56,14 +71,79 @@ func (s *NanoMDMStorage) RetrievePushCert(
 	return &tlsCert, "0", nil
 }
 
+// tenantPushCertVersion identifies the version of the APNs certificate and key
+// of a tenant. As the assets are encrypted with a random nonce, the hashes of
+// their encrypted values change every time they are updated.
+type tenantPushCertVersion struct {
+	TenantID uint   `db:"tenant_id"`
+	Hashes   string `db:"hashes"`
+}
+
+func (v tenantPushCertVersion) staleToken() string {
+	return fmt.Sprintf("%d:%s", v.TenantID, v.Hashes)
+}
+
+const tenantPushCertVersionsStmt = `
+	SELECT tenant_id, GROUP_CONCAT(SHA2(value, 256) ORDER BY name) AS hashes
+	FROM tenant_mdm_assets
+	WHERE name IN (?, ?) %s
+	GROUP BY tenant_id
+	HAVING COUNT(*) = 2`
+
+// retrieveTenantPushCert returns the APNs certificate of the tenant that
+// matches topic, or nil if no tenant has such a certificate. The stale token
+// identifies the tenant and the version of its certificate.
+func (s *NanoMDMStorage) retrieveTenantPushCert(ctx context.Context, topic string) (*tls.Certificate, string, error) {
+	var versions []tenantPushCertVersion
+	if err := sqlx.SelectContext(ctx, s.db, &versions, fmt.Sprintf(tenantPushCertVersionsStmt, ""),
+		fleet.TenantMDMAssetAPNSCert, fleet.TenantMDMAssetAPNSKey); err != nil {
+		return nil, "", ctxerr.Wrap(ctx, err, "selecting tenants APNs certificates")
+	}
+
+	for _, v := range versions {
+		assets, err := getTenantMDMAssetsDB(ctx, s.db, s.serverPrivateKey, v.TenantID,
+			[]fleet.TenantMDMAssetName{fleet.TenantMDMAssetAPNSCert, fleet.TenantMDMAssetAPNSKey})
+		if err != nil {
+			return nil, "", err
+		}
+		certPEM, keyPEM := assets[fleet.TenantMDMAssetAPNSCert], assets[fleet.TenantMDMAssetAPNSKey]
+		tenantTopic, err := cryptoutil.TopicFromPEMCert(certPEM)
+		if err != nil {
+			// an invalid certificate of a tenant must not prevent the
+			// notifications of the other tenants.
+			level.Info(s.logger).Log("msg", "invalid tenant APNs certificate", "tenant_id", v.TenantID, "err", err)
+			continue
+		}
+		if tenantTopic != topic {
+			continue
+		}
+		tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, "", ctxerr.Wrapf(ctx, err, "parsing APNs certificate of tenant %d", v.TenantID)
+		}
+		return &tlsCert, v.staleToken(), nil
+	}
+	return nil, "", nil
+}
+
 // IsPushCertStale partially implements nanomdm_storage.PushCertStore.
 //
-// Given that we are not storing the APNS certificate in MySQL storage, and instead loading
-// them at startup (as env variables), the APNS will never be considered stale.
+// The certificate of a tenant is stale if it was updated or deleted since it
+// was retrieved. Given that the APNs certificate of the server is loaded at
+// startup (as env variables), it is never considered stale.
 //
 // TODO(lucas): Revisit solution to support changing the APNS.
 func (s *NanoMDMStorage) IsPushCertStale(ctx context.Context, topic, staleToken string) (bool, error) {
-	return false, nil
+	tenantID, _, ok := strings.Cut(staleToken, ":")
+	if !ok {
+		return false, nil
+	}
+	var versions []tenantPushCertVersion
+	if err := sqlx.SelectContext(ctx, s.db, &versions, fmt.Sprintf(tenantPushCertVersionsStmt, "AND tenant_id = ?"),
+		fleet.TenantMDMAssetAPNSCert, fleet.TenantMDMAssetAPNSKey, tenantID); err != nil {
+		return false, ctxerr.Wrap(ctx, err, "selecting tenant APNs certificate")
+	}
+	return len(versions) == 0 || versions[0].staleToken() != staleToken, nil
 }
 
 // StorePushCert partially implements nanomdm_storage.PushCertStore.
@@ -138,6 +218,74 @@ func (s *NanoMDMStorage) EnqueueDeviceWipeCommand(ctx context.Context, host *fle
 	}, s.logger)
 }
 
+// EnqueueSetFirmwarePasswordCommand enqueues a SetFirmwarePassword command
+// for the given host, it can only be called for a single host as the new
+// password is specific to the host.
+func (s *NanoMDMStorage) EnqueueSetFirmwarePasswordCommand(ctx context.Context, host *fleet.Host, cmd *mdm.Command, newPassword string) error {
+	encrypted, err := encrypt([]byte(newPassword), s.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypting firmware password")
+	}
+
+	return withRetryTxx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		if err := enqueueCommandDB(ctx, tx, []string{host.UUID}, cmd); err != nil {
+			return err
+		}
+
+		const stmt = `
+			INSERT INTO host_mdm_apple_firmware_passwords
+				(host_id, pending_password, set_command_uuid)
+			VALUES
+				(?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				pending_password = VALUES(pending_password),
+				set_command_uuid = VALUES(set_command_uuid)`
+
+		if _, err := tx.ExecContext(ctx, stmt, host.ID, encrypted, cmd.CommandUUID); err != nil {
+			return ctxerr.Wrap(ctx, err, "modifying host_mdm_apple_firmware_passwords for SetFirmwarePassword")
+		}
+		return nil
+	}, s.logger)
+}
+
+// EnabledEnrollmentIDs returns the subset of the provided enrollment ids that
+// exist and are enabled.
+func (s *NanoMDMStorage) EnabledEnrollmentIDs(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	stmt, args, err := sqlx.In(`SELECT id FROM nano_enrollments WHERE enabled = 1 AND id IN (?)`, ids)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "building enabled enrollments query")
+	}
+	var enabled []string
+	if err := sqlx.SelectContext(ctx, s.db, &enabled, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting enabled enrollments")
+	}
+	return enabled, nil
+}
+
+// EnqueueStoredCommand enqueues a command already stored in nano_commands for
+// the provided enrollment ids.
+func (s *NanoMDMStorage) EnqueueStoredCommand(ctx context.Context, ids []string, commandUUID string) error {
+	if len(ids) < 1 {
+		return errors.New("no id(s) supplied to queue command to")
+	}
+
+	stmt := `INSERT INTO nano_enrollment_queue (id, command_uuid) VALUES (?, ?)`
+	stmt += strings.Repeat(", (?, ?)", len(ids)-1)
+	args := make([]interface{}, len(ids)*2)
+	for i, id := range ids {
+		args[i*2] = id
+		args[i*2+1] = commandUUID
+	}
+	if _, err := s.db.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing stored command")
+	}
+	return nil
+}
+
 // NewMDMAppleDEPStorage returns a MySQL nanodep storage that uses the Datastore
 // underlying MySQL writer *sql.DB.
 func (ds *Datastore) NewMDMAppleDEPStorage(tok nanodep_client.OAuth1Tokens) (*NanoDEPStorage, error) {
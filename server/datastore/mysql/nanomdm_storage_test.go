@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ func TestNanoMDMStorage(t *testing.T) {
 		fn   func(t *testing.T, ds *Datastore)
 	}{
 		{"TestEnqueueDeviceLockCommand", testEnqueueDeviceLockCommand},
+		{"TestEnqueueCommandInBatches", testEnqueueCommandInBatches},
 	}
 
 	for _, c := range cases {
@@ -83,3 +85,59 @@ func testEnqueueDeviceLockCommand(t *testing.T, ds *Datastore) {
 	require.Equal(t, "cmd-uuid", status.LockMDMCommand.CommandUUID)
 	require.Equal(t, "123456", status.UnlockPIN)
 }
+
+func testEnqueueCommandInBatches(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	ns, err := ds.NewMDMAppleMDMStorage(nil, nil)
+	require.NoError(t, err)
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		host, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:      fmt.Sprintf("test-host%d-name", i),
+			OsqueryHostID: ptr.String(fmt.Sprintf("osquery-%d", i)),
+			NodeKey:       ptr.String(fmt.Sprintf("nodekey-%d", i)),
+			UUID:          fmt.Sprintf("test-uuid-%d", i),
+			Platform:      "darwin",
+		})
+		require.NoError(t, err)
+		hosts = append(hosts, host)
+	}
+	nanoEnroll(t, ds, hosts[0], false)
+	nanoEnroll(t, ds, hosts[1], false)
+	// the enrollment of the second host is disabled, the third host is not enrolled
+	_, err = ds.writer(ctx).ExecContext(ctx, `UPDATE nano_enrollments SET enabled = 0 WHERE id = ?`, hosts[1].UUID)
+	require.NoError(t, err)
+
+	enabled, err := ns.EnabledEnrollmentIDs(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, enabled)
+	enabled, err = ns.EnabledEnrollmentIDs(ctx, []string{hosts[0].UUID, hosts[1].UUID, hosts[2].UUID})
+	require.NoError(t, err)
+	require.Equal(t, []string{hosts[0].UUID}, enabled)
+
+	// enable the second host and enqueue a command in two batches
+	_, err = ds.writer(ctx).ExecContext(ctx, `UPDATE nano_enrollments SET enabled = 1 WHERE id = ?`, hosts[1].UUID)
+	require.NoError(t, err)
+
+	cmd := &mdm.Command{}
+	cmd.CommandUUID = "cmd-uuid"
+	cmd.Command.RequestType = "ShutDownDevice"
+	cmd.Raw = []byte("<?xml")
+	_, err = ns.EnqueueCommand(ctx, []string{hosts[0].UUID}, cmd)
+	require.NoError(t, err)
+	err = ns.EnqueueStoredCommand(ctx, []string{hosts[1].UUID}, cmd.CommandUUID)
+	require.NoError(t, err)
+	err = ns.EnqueueStoredCommand(ctx, nil, cmd.CommandUUID)
+	require.Error(t, err)
+
+	res, err := ds.ListMDMAppleCommands(ctx, fleet.TeamFilter{User: test.UserAdmin}, &fleet.MDMCommandListOptions{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	var deviceIDs []string
+	for _, r := range res {
+		require.Equal(t, cmd.CommandUUID, r.CommandUUID)
+		deviceIDs = append(deviceIDs, r.DeviceID)
+	}
+	require.ElementsMatch(t, []string{hosts[0].UUID, hosts[1].UUID}, deviceIDs)
+}
@@ -1070,6 +1070,73 @@ func (ds *Datastore) OutdatedAutomationBatch(ctx context.Context) ([]fleet.Polic
 	return failures, nil
 }
 
+func (ds *Datastore) ListPolicyFailingHosts(ctx context.Context, policyID uint) ([]fleet.PolicySetHost, error) {
+	// the display name is the computer name, as for the failing hosts of
+	// OutdatedAutomationBatch.
+	const stmt = `
+		SELECT h.id, h.hostname, h.computer_name AS display_name
+			FROM policy_membership pm
+			JOIN hosts h ON pm.host_id = h.id
+			WHERE pm.policy_id = ? AND NOT pm.passes
+			ORDER BY h.id`
+
+	var hosts []struct {
+		ID          uint   `db:"id"`
+		Hostname    string `db:"hostname"`
+		DisplayName string `db:"display_name"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt, policyID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list policy failing hosts")
+	}
+	res := make([]fleet.PolicySetHost, 0, len(hosts))
+	for _, h := range hosts {
+		res = append(res, fleet.PolicySetHost{ID: h.ID, Hostname: h.Hostname, DisplayName: h.DisplayName})
+	}
+	return res, nil
+}
+
+func (ds *Datastore) StreamPolicyMembership(ctx context.Context, policyID uint, passes bool, batchSize int, fn func([]fleet.PolicySetHost) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+
+	// the display name is the computer name, as for ListPolicyFailingHosts.
+	const stmt = `
+		SELECT h.id, h.hostname, h.computer_name AS display_name
+			FROM policy_membership pm
+			JOIN hosts h ON pm.host_id = h.id
+			WHERE pm.policy_id = ? AND pm.passes = ? AND pm.host_id > ?
+			ORDER BY pm.host_id
+			LIMIT ?`
+
+	var lastID uint
+	for {
+		var hosts []struct {
+			ID          uint   `db:"id"`
+			Hostname    string `db:"hostname"`
+			DisplayName string `db:"display_name"`
+		}
+		if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt, policyID, passes, lastID, batchSize); err != nil {
+			return ctxerr.Wrap(ctx, err, "stream policy membership")
+		}
+		if len(hosts) == 0 {
+			return nil
+		}
+
+		batch := make([]fleet.PolicySetHost, 0, len(hosts))
+		for _, h := range hosts {
+			batch = append(batch, fleet.PolicySetHost{ID: h.ID, Hostname: h.Hostname, DisplayName: h.DisplayName})
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(hosts) < batchSize {
+			return nil
+		}
+		lastID = hosts[len(hosts)-1].ID
+	}
+}
+
 func incrementViolationDaysDB(ctx context.Context, tx sqlx.ExtContext) error {
 	const (
 		statsID        = 0
@@ -50,6 +50,8 @@ func TestPolicies(t *testing.T) {
 		{"PolicyViolationDays", testPolicyViolationDays},
 		{"IncreasePolicyAutomationIteration", testIncreasePolicyAutomationIteration},
 		{"OutdatedAutomationBatch", testOutdatedAutomationBatch},
+		{"ListPolicyFailingHosts", testListPolicyFailingHosts},
+		{"StreamPolicyMembership", testStreamPolicyMembership},
 		{"TestUpdatePolicyFailureCountsForHosts", testUpdatePolicyFailureCountsForHosts},
 		{"TestListGlobalPoliciesCanPaginate", testListGlobalPoliciesCanPaginate},
 		{"TestListTeamPoliciesCanPaginate", testListTeamPoliciesCanPaginate},
@@ -3583,3 +3585,102 @@ func testGetTeamHostsPolicyMemberships(t *testing.T, ds *Datastore) {
 	require.Equal(t, "serial2", hostsTeam2[0].HostHardwareSerial)
 	require.Equal(t, "display_name2", hostsTeam2[0].HostDisplayName)
 }
+
+func testListPolicyFailingHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	h1, err := ds.NewHost(ctx, &fleet.Host{OsqueryHostID: ptr.String("host1"), NodeKey: ptr.String("host1"), Hostname: "h1.local", ComputerName: "h1"})
+	require.NoError(t, err)
+	h2, err := ds.NewHost(ctx, &fleet.Host{OsqueryHostID: ptr.String("host2"), NodeKey: ptr.String("host2"), Hostname: "h2.local", ComputerName: "h2"})
+	require.NoError(t, err)
+	h3, err := ds.NewHost(ctx, &fleet.Host{OsqueryHostID: ptr.String("host3"), NodeKey: ptr.String("host3"), Hostname: "h3.local", ComputerName: "h3"})
+	require.NoError(t, err)
+
+	pol1, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "policy1"})
+	require.NoError(t, err)
+	pol2, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "policy2"})
+	require.NoError(t, err)
+
+	hosts, err := ds.ListPolicyFailingHosts(ctx, pol1.ID)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	err = ds.RecordPolicyQueryExecutions(ctx, h1, map[uint]*bool{pol1.ID: ptr.Bool(false), pol2.ID: ptr.Bool(true)}, time.Now(), false)
+	require.NoError(t, err)
+	err = ds.RecordPolicyQueryExecutions(ctx, h2, map[uint]*bool{pol1.ID: ptr.Bool(true), pol2.ID: ptr.Bool(true)}, time.Now(), false)
+	require.NoError(t, err)
+	err = ds.RecordPolicyQueryExecutions(ctx, h3, map[uint]*bool{pol1.ID: ptr.Bool(false), pol2.ID: nil}, time.Now(), false)
+	require.NoError(t, err)
+
+	hosts, err = ds.ListPolicyFailingHosts(ctx, pol1.ID)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.PolicySetHost{
+		{ID: h1.ID, Hostname: "h1.local", DisplayName: "h1"},
+		{ID: h3.ID, Hostname: "h3.local", DisplayName: "h3"},
+	}, hosts)
+
+	// passing and unanswered policies have no failing hosts
+	hosts, err = ds.ListPolicyFailingHosts(ctx, pol2.ID)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+}
+
+func testStreamPolicyMembership(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 5; i++ {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			OsqueryHostID: ptr.String(fmt.Sprintf("host%d", i)),
+			NodeKey:       ptr.String(fmt.Sprintf("host%d", i)),
+			Hostname:      fmt.Sprintf("h%d.local", i),
+			ComputerName:  fmt.Sprintf("h%d", i),
+		})
+		require.NoError(t, err)
+		hosts = append(hosts, h)
+	}
+
+	pol, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "policy1"})
+	require.NoError(t, err)
+
+	// hosts 0, 1, 3 and 4 fail the policy, host 2 passes it
+	for i, h := range hosts {
+		err = ds.RecordPolicyQueryExecutions(ctx, h, map[uint]*bool{pol.ID: ptr.Bool(i == 2)}, time.Now(), false)
+		require.NoError(t, err)
+	}
+
+	stream := func(passes bool, batchSize int) [][]uint {
+		var batches [][]uint
+		err := ds.StreamPolicyMembership(ctx, pol.ID, passes, batchSize, func(batch []fleet.PolicySetHost) error {
+			ids := make([]uint, 0, len(batch))
+			for _, h := range batch {
+				ids = append(ids, h.ID)
+			}
+			batches = append(batches, ids)
+			return nil
+		})
+		require.NoError(t, err)
+		return batches
+	}
+
+	require.Equal(t, [][]uint{{hosts[0].ID, hosts[1].ID}, {hosts[3].ID, hosts[4].ID}}, stream(false, 2))
+	require.Equal(t, [][]uint{{hosts[0].ID, hosts[1].ID, hosts[3].ID}, {hosts[4].ID}}, stream(false, 3))
+	require.Equal(t, [][]uint{{hosts[2].ID}}, stream(true, 2))
+	require.Equal(t, [][]uint{{hosts[0].ID, hosts[1].ID, hosts[3].ID, hosts[4].ID}}, stream(false, 0))
+
+	// the display name is loaded
+	err = ds.StreamPolicyMembership(ctx, pol.ID, true, 10, func(batch []fleet.PolicySetHost) error {
+		require.Equal(t, []fleet.PolicySetHost{{ID: hosts[2].ID, Hostname: "h2.local", DisplayName: "h2"}}, batch)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// streaming stops at the first error
+	var calls int
+	err = ds.StreamPolicyMembership(ctx, pol.ID, false, 1, func(batch []fleet.PolicySetHost) error {
+		calls++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) RecordPolicyComplianceHistory(ctx context.Context, date time.Time) error {
+	// The counts of a team policy are stored with inherited_team_id = 0 in
+	// policy_stats, they are recorded under the policy's team as they are
+	// the counts of the team's hosts. The counts of the global policies on
+	// all hosts are recorded under team 0.
+	const stmt = `
+		INSERT INTO policy_compliance_daily_counts (date, team_id, policy_id, passing_host_count, failing_host_count)
+		SELECT
+			?,
+			IF(ps.inherited_team_id = 0, COALESCE(p.team_id, 0), ps.inherited_team_id),
+			ps.policy_id,
+			ps.passing_host_count,
+			ps.failing_host_count
+		FROM policy_stats ps
+		INNER JOIN policies p ON p.id = ps.policy_id
+		ON DUPLICATE KEY UPDATE
+			passing_host_count = VALUES(passing_host_count),
+			failing_host_count = VALUES(failing_host_count)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, date.UTC().Format("2006-01-02")); err != nil {
+		return ctxerr.Wrap(ctx, err, "record policy compliance history")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupPolicyComplianceHistory(ctx context.Context, olderThan time.Time) error {
+	day := olderThan.UTC().Format("2006-01-02")
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM policy_compliance_daily_counts WHERE date < ?`, day); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup policy compliance history")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetPolicyComplianceTrends(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) ([]fleet.PolicyComplianceDay, error) {
+	var teamID uint
+	if opts.TeamID != nil {
+		teamID = *opts.TeamID
+	}
+
+	stmt := `
+		SELECT date, SUM(passing_host_count) AS passing_host_count, SUM(failing_host_count) AS failing_host_count
+		FROM policy_compliance_daily_counts
+		WHERE team_id = ? AND date >= ? AND date <= ?`
+	args := []interface{}{teamID, opts.StartDate.UTC().Format("2006-01-02"), opts.EndDate.UTC().Format("2006-01-02")}
+	if opts.PolicyID != nil {
+		stmt += ` AND policy_id = ?`
+		args = append(args, *opts.PolicyID)
+	}
+	stmt += `
+		GROUP BY date
+		ORDER BY date`
+
+	days := []fleet.PolicyComplianceDay{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &days, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select policy compliance trends")
+	}
+	return days, nil
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyComplianceHistory(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	gp, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "global", Query: "SELECT 1;"})
+	require.NoError(t, err)
+	tp, err := ds.NewTeamPolicy(ctx, team.ID, nil, fleet.PolicyPayload{Name: "team", Query: "SELECT 1;"})
+	require.NoError(t, err)
+
+	setStats := func(policyID, inheritedTeamID, passing, failing uint) {
+		ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			_, err := q.ExecContext(ctx, `
+				INSERT INTO policy_stats (policy_id, inherited_team_id, passing_host_count, failing_host_count)
+				VALUES (?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE passing_host_count = VALUES(passing_host_count), failing_host_count = VALUES(failing_host_count)`,
+				policyID, inheritedTeamID, passing, failing)
+			return err
+		})
+	}
+
+	day1 := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	// global policy on all hosts, inherited by the team, and team policy
+	setStats(gp.ID, 0, 5, 5)
+	setStats(gp.ID, team.ID, 2, 3)
+	setStats(tp.ID, 0, 1, 4)
+	require.NoError(t, ds.RecordPolicyComplianceHistory(ctx, day1))
+
+	setStats(gp.ID, 0, 8, 2)
+	setStats(gp.ID, team.ID, 4, 1)
+	setStats(tp.ID, 0, 3, 2)
+	require.NoError(t, ds.RecordPolicyComplianceHistory(ctx, day2))
+	// the counts of the day are updated
+	setStats(tp.ID, 0, 5, 0)
+	require.NoError(t, ds.RecordPolicyComplianceHistory(ctx, day2.Add(time.Hour)))
+
+	days, err := ds.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{StartDate: day1, EndDate: day3})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.PolicyComplianceDay{
+		{Date: day1, PassingHostCount: 5, FailingHostCount: 5},
+		{Date: day2, PassingHostCount: 8, FailingHostCount: 2},
+	}, days)
+
+	days, err = ds.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{TeamID: &team.ID, StartDate: day1, EndDate: day3})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.PolicyComplianceDay{
+		{Date: day1, PassingHostCount: 3, FailingHostCount: 7},
+		{Date: day2, PassingHostCount: 9, FailingHostCount: 1},
+	}, days)
+
+	days, err = ds.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{TeamID: &team.ID, PolicyID: ptr.Uint(tp.ID), StartDate: day2, EndDate: day2})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.PolicyComplianceDay{{Date: day2, PassingHostCount: 5, FailingHostCount: 0}}, days)
+
+	require.NoError(t, ds.CleanupPolicyComplianceHistory(ctx, day2))
+	days, err = ds.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{StartDate: day1, EndDate: day3})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.PolicyComplianceDay{{Date: day2, PassingHostCount: 8, FailingHostCount: 2}}, days)
+
+	days, err = ds.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{StartDate: day3, EndDate: day3})
+	require.NoError(t, err)
+	require.Empty(t, days)
+	require.NotNil(t, days)
+}
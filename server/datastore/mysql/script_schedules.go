@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// scriptScheduleSelectStmt selects the script schedules along with the name
+// and team of their script.
+const scriptScheduleSelectStmt = `
+	SELECT
+		ss.id,
+		ss.script_id,
+		s.name AS script_name,
+		s.team_id,
+		ss.local_time,
+		ss.author_id,
+		ss.created_at,
+		ss.updated_at
+	FROM script_schedules ss
+	JOIN scripts s ON s.id = ss.script_id
+`
+
+func (ds *Datastore) NewScriptSchedule(ctx context.Context, schedule *fleet.ScriptSchedule) (*fleet.ScriptSchedule, error) {
+	const stmt = `INSERT INTO script_schedules (script_id, local_time, author_id) VALUES (?, ?, ?)`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, schedule.ScriptID, schedule.LocalTime, schedule.AuthorID)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("ScriptSchedule", schedule.LocalTime))
+		}
+		if isChildForeignKeyError(err) {
+			return nil, ctxerr.Wrap(ctx, notFound("Script").WithID(schedule.ScriptID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "inserting script schedule")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return ds.scriptScheduleByID(ctx, ds.writer(ctx), uint(id))
+}
+
+func (ds *Datastore) ScriptSchedule(ctx context.Context, id uint) (*fleet.ScriptSchedule, error) {
+	return ds.scriptScheduleByID(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) scriptScheduleByID(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.ScriptSchedule, error) {
+	var schedule fleet.ScriptSchedule
+	if err := sqlx.GetContext(ctx, q, &schedule, scriptScheduleSelectStmt+` WHERE ss.id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("ScriptSchedule").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting script schedule by id")
+	}
+	return &schedule, nil
+}
+
+func (ds *Datastore) ListScriptSchedules(ctx context.Context, teamID *uint) ([]*fleet.ScriptSchedule, error) {
+	var globalOrTeamID uint
+	if teamID != nil {
+		globalOrTeamID = *teamID
+	}
+	stmt := scriptScheduleSelectStmt + ` WHERE s.global_or_team_id = ? ORDER BY s.name, ss.local_time`
+
+	schedules := []*fleet.ScriptSchedule{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &schedules, stmt, globalOrTeamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing script schedules")
+	}
+	return schedules, nil
+}
+
+func (ds *Datastore) DeleteScriptSchedule(ctx context.Context, id uint) error {
+	// the host runs of the schedule are deleted by the foreign key.
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM script_schedules WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete script schedule")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("ScriptSchedule").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) ListScriptScheduleHosts(ctx context.Context) ([]*fleet.ScriptScheduleHost, error) {
+	// a schedule targets the hosts of the team (or no team) of its script that
+	// run fleetd with scripts enabled (scripts_enabled is NULL for older
+	// versions of fleetd).
+	const stmt = `
+	SELECT
+		ss.id AS script_schedule_id,
+		s.id AS script_id,
+		s.name AS script_name,
+		COALESCE(s.script_content_id, 0) AS script_content_id,
+		ss.local_time,
+		h.id AS host_id,
+		h.platform AS host_platform,
+		hoi.timezone AS host_timezone,
+		DATE_FORMAT(sshr.last_run_date, '%Y-%m-%d') AS last_run_date
+	FROM script_schedules ss
+	JOIN scripts s ON s.id = ss.script_id
+	JOIN hosts h ON h.team_id <=> s.team_id
+	LEFT JOIN host_orbit_info hoi ON hoi.host_id = h.id
+	LEFT JOIN script_schedule_host_runs sshr ON sshr.script_schedule_id = ss.id AND sshr.host_id = h.id
+	WHERE
+		h.orbit_node_key IS NOT NULL AND
+		h.orbit_node_key != '' AND
+		COALESCE(hoi.scripts_enabled, 1) = 1
+	ORDER BY ss.id, h.id`
+
+	hosts := []*fleet.ScriptScheduleHost{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hosts, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing script schedule hosts")
+	}
+	return hosts, nil
+}
+
+func (ds *Datastore) NewScheduledHostScriptExecutionRequest(ctx context.Context, request *fleet.HostScriptRequestPayload, scheduleID uint, localDate string) (*fleet.HostScriptResult, error) {
+	const runStmt = `
+	INSERT INTO script_schedule_host_runs (script_schedule_id, host_id, last_run_date)
+	VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE last_run_date = VALUES(last_run_date)`
+
+	var res *fleet.HostScriptResult
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, runStmt, scheduleID, request.HostID, localDate); err != nil {
+			return ctxerr.Wrap(ctx, err, "record script schedule host run")
+		}
+
+		var err error
+		res, err = newHostScriptExecutionRequest(ctx, request, tx)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptSchedules(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ScriptSchedules", testScriptSchedules},
+		{"ListScriptScheduleHosts", testListScriptScheduleHosts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testScriptSchedules(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tm, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	u := test.NewUser(t, ds, "user", "user@example.com", true)
+
+	noTeamScript, err := ds.NewScript(ctx, &fleet.Script{Name: "a.sh", ScriptContents: "echo"})
+	require.NoError(t, err)
+	teamScript, err := ds.NewScript(ctx, &fleet.Script{Name: "b.sh", TeamID: &tm.ID, ScriptContents: "echo"})
+	require.NoError(t, err)
+
+	// no schedule yet
+	schedules, err := ds.ListScriptSchedules(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, schedules)
+	_, err = ds.ScriptSchedule(ctx, 1)
+	require.True(t, fleet.IsNotFound(err))
+
+	s1, err := ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: noTeamScript.ID, LocalTime: "02:00", AuthorID: &u.ID})
+	require.NoError(t, err)
+	require.NotZero(t, s1.ID)
+	require.Equal(t, "a.sh", s1.ScriptName)
+	require.Nil(t, s1.TeamID)
+	require.Equal(t, "02:00", s1.LocalTime)
+	require.Equal(t, &u.ID, s1.AuthorID)
+
+	s2, err := ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: noTeamScript.ID, LocalTime: "14:30"})
+	require.NoError(t, err)
+	s3, err := ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: teamScript.ID, LocalTime: "02:00"})
+	require.NoError(t, err)
+	require.Equal(t, &tm.ID, s3.TeamID)
+
+	// same script at the same time
+	_, err = ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: noTeamScript.ID, LocalTime: "02:00"})
+	var existsErr fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &existsErr)
+
+	// unknown script
+	_, err = ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: teamScript.ID + 100, LocalTime: "02:00"})
+	require.True(t, fleet.IsNotFound(err))
+
+	got, err := ds.ScriptSchedule(ctx, s2.ID)
+	require.NoError(t, err)
+	require.Equal(t, s2, got)
+
+	schedules, err = ds.ListScriptSchedules(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	require.Equal(t, s1.ID, schedules[0].ID)
+	require.Equal(t, s2.ID, schedules[1].ID)
+	schedules, err = ds.ListScriptSchedules(ctx, &tm.ID)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	require.Equal(t, s3.ID, schedules[0].ID)
+
+	err = ds.DeleteScriptSchedule(ctx, s2.ID)
+	require.NoError(t, err)
+	err = ds.DeleteScriptSchedule(ctx, s2.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// deleting the script deletes its schedules
+	err = ds.DeleteScript(ctx, teamScript.ID)
+	require.NoError(t, err)
+	_, err = ds.ScriptSchedule(ctx, s3.ID)
+	require.True(t, fleet.IsNotFound(err))
+	schedules, err = ds.ListScriptSchedules(ctx, &tm.ID)
+	require.NoError(t, err)
+	require.Empty(t, schedules)
+}
+
+func testListScriptScheduleHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tm, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	newOrbitHost := func(name string, orbitKey *string) *fleet.Host {
+		return test.NewHost(t, ds, name, "", name+"key", name+"uuid", time.Now(), func(h *fleet.Host) {
+			h.OrbitNodeKey = orbitKey
+		})
+	}
+	h1 := newOrbitHost("h1", ptr.String("orbit1"))
+	h2 := newOrbitHost("h2", ptr.String("orbit2"))
+	newOrbitHost("h3", nil) // no fleetd, never targeted
+	h4 := newOrbitHost("h4", ptr.String("orbit4"))
+	h5 := newOrbitHost("h5", ptr.String("orbit5"))
+	require.NoError(t, ds.AddHostsToTeam(ctx, &tm.ID, []uint{h5.ID}))
+
+	require.NoError(t, ds.SetOrUpdateHostOrbitInfo(ctx, h1.ID, "1.24.0", sql.NullString{}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{String: "Europe/Paris", Valid: true}))
+	// scripts disabled
+	require.NoError(t, ds.SetOrUpdateHostOrbitInfo(ctx, h4.ID, "1.24.0", sql.NullString{}, sql.NullBool{Bool: false, Valid: true}, sql.NullString{}))
+
+	// no schedule
+	hosts, err := ds.ListScriptScheduleHosts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+
+	script, err := ds.NewScript(ctx, &fleet.Script{Name: "a.sh", ScriptContents: "echo"})
+	require.NoError(t, err)
+	teamScript, err := ds.NewScript(ctx, &fleet.Script{Name: "b.sh", TeamID: &tm.ID, ScriptContents: "echo"})
+	require.NoError(t, err)
+	s1, err := ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: script.ID, LocalTime: "02:00"})
+	require.NoError(t, err)
+	s2, err := ds.NewScriptSchedule(ctx, &fleet.ScriptSchedule{ScriptID: teamScript.ID, LocalTime: "03:00"})
+	require.NoError(t, err)
+
+	hosts, err = ds.ListScriptScheduleHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	require.Equal(t, s1.ID, hosts[0].ScriptScheduleID)
+	require.Equal(t, h1.ID, hosts[0].HostID)
+	require.Equal(t, script.ID, hosts[0].ScriptID)
+	require.Equal(t, "a.sh", hosts[0].ScriptName)
+	require.NotZero(t, hosts[0].ScriptContentID)
+	require.Equal(t, "02:00", hosts[0].LocalTime)
+	require.Equal(t, "darwin", hosts[0].HostPlatform)
+	require.Equal(t, ptr.String("Europe/Paris"), hosts[0].HostTimezone)
+	require.Nil(t, hosts[0].LastRunDate)
+	require.Equal(t, s1.ID, hosts[1].ScriptScheduleID)
+	require.Equal(t, h2.ID, hosts[1].HostID)
+	require.Nil(t, hosts[1].HostTimezone)
+	require.Equal(t, s2.ID, hosts[2].ScriptScheduleID)
+	require.Equal(t, h5.ID, hosts[2].HostID)
+	require.Equal(t, "03:00", hosts[2].LocalTime)
+
+	// queue the scheduled script on h1
+	res, err := ds.NewScheduledHostScriptExecutionRequest(ctx, &fleet.HostScriptRequestPayload{
+		HostID:          h1.ID,
+		ScriptID:        &script.ID,
+		ScriptContentID: hosts[0].ScriptContentID,
+	}, s1.ID, "2024-05-02")
+	require.NoError(t, err)
+	require.Equal(t, h1.ID, res.HostID)
+	require.Equal(t, &script.ID, res.ScriptID)
+	require.NotEmpty(t, res.ExecutionID)
+
+	pending, err := ds.IsExecutionPendingForHost(ctx, h1.ID, script.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	hosts, err = ds.ListScriptScheduleHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	require.Equal(t, ptr.String("2024-05-02"), hosts[0].LastRunDate)
+	require.Nil(t, hosts[1].LastRunDate)
+
+	// a later run updates the last run date
+	_, err = ds.NewScheduledHostScriptExecutionRequest(ctx, &fleet.HostScriptRequestPayload{
+		HostID:          h1.ID,
+		ScriptID:        &script.ID,
+		ScriptContentID: hosts[0].ScriptContentID,
+	}, s1.ID, "2024-05-03")
+	require.NoError(t, err)
+	hosts, err = ds.ListScriptScheduleHosts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ptr.String("2024-05-03"), hosts[0].LastRunDate)
+}
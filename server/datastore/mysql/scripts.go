@@ -93,6 +93,8 @@ func (ds *Datastore) SetHostScriptExecutionResult(ctx context.Context, result *f
       WHEN lock_ref = ? THEN 'lock_ref'
       WHEN unlock_ref = ? THEN 'unlock_ref'
       WHEN wipe_ref = ? THEN 'wipe_ref'
+      WHEN isolate_ref = ? THEN 'isolate_ref'
+      WHEN unisolate_ref = ? THEN 'unisolate_ref'
       ELSE ''
     END AS ref_col
   FROM
@@ -148,10 +150,12 @@ func (ds *Datastore) SetHostScriptExecutionResult(ctx context.Context, result *f
 				return ctxerr.Wrap(ctx, err, "load updated host script result")
 			}
 
-			// look up if that script was a lock/unlock/wipe script for that host,
-			// and if so update the host_mdm_actions table accordingly.
+			// look up if that script was a lock/unlock/wipe/isolate/unisolate
+			// script for that host, and if so update the host_mdm_actions table
+			// accordingly.
 			var refCol string
-			err = sqlx.GetContext(ctx, tx, &refCol, hostMDMActionsStmt, result.ExecutionID, result.ExecutionID, result.ExecutionID, result.HostID)
+			err = sqlx.GetContext(ctx, tx, &refCol, hostMDMActionsStmt, result.ExecutionID, result.ExecutionID, result.ExecutionID,
+				result.ExecutionID, result.ExecutionID, result.HostID)
 			if err != nil && !errors.Is(err, sql.ErrNoRows) { // ignore ErrNoRows, refCol will be empty
 				return ctxerr.Wrap(ctx, err, "lookup host script corresponding mdm action")
 			}
@@ -343,7 +347,8 @@ SELECT
   name,
   created_at,
   updated_at,
-  script_content_id
+  script_content_id,
+  shared_with_all_teams
 FROM
   scripts
 WHERE
@@ -397,19 +402,35 @@ SELECT
   s.team_id,
   s.name,
   s.created_at,
-  s.updated_at
+  s.updated_at,
+  s.shared_with_all_teams,
+  %s AS opted_out
 FROM
   scripts s
 WHERE
-  s.global_or_team_id = ?
+  s.global_or_team_id = ?%s
 `
 	var globalOrTeamID uint
 	if teamID != nil {
 		globalOrTeamID = *teamID
 	}
 
+	// the scripts of a team include the scripts shared with all teams, with
+	// whether the team opted out of them.
+	optedOut, sharedCond := "NULL", ""
 	args := []any{globalOrTeamID}
-	stmt, args := appendListOptionsWithCursorToSQL(selectStmt, args, &opt)
+	if globalOrTeamID > 0 {
+		optedOut = `CASE WHEN s.global_or_team_id = 0 THEN
+    EXISTS (SELECT 1 FROM team_shared_library_opt_outs tslo WHERE tslo.team_id = ? AND tslo.script_id = s.id)
+  END`
+		var sharedArgs []any
+		sharedCond, sharedArgs = sharedScriptsCondition("s", globalOrTeamID, false)
+		sharedCond = " OR " + sharedCond
+		// the opted_out placeholder comes first
+		args = append([]any{globalOrTeamID}, args...)
+		args = append(args, sharedArgs...)
+	}
+	stmt, args := appendListOptionsWithCursorToSQL(fmt.Sprintf(selectStmt, optedOut, sharedCond), args, &opt)
 
 	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &scripts, stmt, args...); err != nil {
 		return nil, nil, ctxerr.Wrap(ctx, err, "select scripts")
@@ -429,20 +450,32 @@ WHERE
 func (ds *Datastore) GetScriptIDByName(ctx context.Context, name string, teamID *uint) (uint, error) {
 	const selectStmt = `
 SELECT
-  id
+  s.id
 FROM
-  scripts
+  scripts s
 WHERE
-  global_or_team_id = ?
-  AND name = ?
+  (s.global_or_team_id = ?%s)
+  AND s.name = ?
 `
 	var globalOrTeamID uint
 	if teamID != nil {
 		globalOrTeamID = *teamID
 	}
 
+	// a team's script takes precedence over the shared script with the same
+	// name, so at most one script matches.
+	var sharedCond string
+	args := []any{globalOrTeamID}
+	if globalOrTeamID > 0 {
+		var sharedArgs []any
+		sharedCond, sharedArgs = sharedScriptsCondition("s", globalOrTeamID, true)
+		sharedCond = " OR " + sharedCond
+		args = append(args, sharedArgs...)
+	}
+	args = append(args, name)
+
 	var id uint
-	if err := sqlx.GetContext(ctx, ds.reader(ctx), &id, selectStmt, globalOrTeamID, name); err != nil {
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &id, fmt.Sprintf(selectStmt, sharedCond), args...); err != nil {
 		if err == sql.ErrNoRows {
 			return 0, notFound("Script").WithName(name)
 		}
@@ -515,10 +548,18 @@ FROM
 	ON s.id = hsr.script_id
 WHERE
 	(hsr.host_id IS NULL OR hsr.host_id = ?)
-	AND s.global_or_team_id = ?
+	AND (s.global_or_team_id = ?%s)
 	`
 
 	args := []any{hostID, hostID, hostID, globalOrTeamID}
+	var sharedCond string
+	if globalOrTeamID > 0 {
+		var sharedArgs []any
+		sharedCond, sharedArgs = sharedScriptsCondition("s", globalOrTeamID, true)
+		sharedCond = " OR " + sharedCond
+		args = append(args, sharedArgs...)
+	}
+	sql = fmt.Sprintf(sql, sharedCond)
 	if len(extension) > 0 {
 		args = append(args, extension)
 		sql += `
@@ -665,7 +706,9 @@ func (ds *Datastore) GetHostLockWipeStatus(ctx context.Context, host *fleet.Host
 			wipe_ref,
 			unlock_ref,
 			unlock_pin,
-			fleet_platform
+			fleet_platform,
+			isolate_ref,
+			unisolate_ref
 		FROM
 			host_mdm_actions
 		WHERE
@@ -678,6 +721,8 @@ func (ds *Datastore) GetHostLockWipeStatus(ctx context.Context, host *fleet.Host
 		UnlockRef     *string `db:"unlock_ref"`
 		UnlockPIN     *string `db:"unlock_pin"`
 		FleetPlatform string  `db:"fleet_platform"`
+		IsolateRef    *string `db:"isolate_ref"`
+		UnisolateRef  *string `db:"unisolate_ref"`
 	}
 	fleetPlatform := host.FleetPlatform()
 	status := &fleet.HostLockWipeStatus{
@@ -775,6 +820,22 @@ func (ds *Datastore) GetHostLockWipeStatus(ctx context.Context, host *fleet.Host
 		}
 	}
 
+	// isolate and unisolate references are scripts on all platforms
+	if mdmActions.IsolateRef != nil {
+		hsr, err := ds.getHostScriptExecutionResultDB(ctx, ds.reader(ctx), *mdmActions.IsolateRef)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get isolate reference script result")
+		}
+		status.IsolateScript = hsr
+	}
+	if mdmActions.UnisolateRef != nil {
+		hsr, err := ds.getHostScriptExecutionResultDB(ctx, ds.reader(ctx), *mdmActions.UnisolateRef)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get unisolate reference script result")
+		}
+		status.UnisolateScript = hsr
+	}
+
 	return status, nil
 }
 
@@ -988,6 +1049,69 @@ func (ds *Datastore) WipeHostViaScript(ctx context.Context, request *fleet.HostS
 	})
 }
 
+// IsolateHostViaScript creates the script execution request and updates the
+// host_mdm_actions table in a single transaction.
+func (ds *Datastore) IsolateHostViaScript(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error {
+	// on duplicate we don't clear any other existing state because at this
+	// point in time, this is just a request to isolate the host that is
+	// recorded, it is pending execution. The host's state should be updated to
+	// "isolated" only when the script execution is successfully completed, and
+	// then any unisolate reference should be cleared.
+	const stmt = `
+	INSERT INTO host_mdm_actions
+	(
+		host_id,
+		isolate_ref,
+		fleet_platform
+	)
+	VALUES (?,?,?)
+	ON DUPLICATE KEY UPDATE
+		isolate_ref = VALUES(isolate_ref)
+	`
+	return ds.hostIsolationViaScript(ctx, request, hostFleetPlatform, "isolate", stmt)
+}
+
+// UnisolateHostViaScript creates the script execution request and updates
+// the host_mdm_actions table in a single transaction.
+func (ds *Datastore) UnisolateHostViaScript(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error {
+	// the host stays isolated until the script execution is successfully
+	// completed, so the isolate reference is kept.
+	const stmt = `
+	INSERT INTO host_mdm_actions
+	(
+		host_id,
+		unisolate_ref,
+		fleet_platform
+	)
+	VALUES (?,?,?)
+	ON DUPLICATE KEY UPDATE
+		unisolate_ref = VALUES(unisolate_ref)
+	`
+	return ds.hostIsolationViaScript(ctx, request, hostFleetPlatform, "unisolate", stmt)
+}
+
+func (ds *Datastore) hostIsolationViaScript(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform, action, upsertStmt string) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		scRes, err := insertScriptContents(ctx, request.ScriptContents, tx)
+		if err != nil {
+			return err
+		}
+
+		id, _ := scRes.LastInsertId()
+		request.ScriptContentID = uint(id)
+
+		res, err := newHostScriptExecutionRequest(ctx, request, tx)
+		if err != nil {
+			return ctxerr.Wrapf(ctx, err, "%s host via script create execution", action)
+		}
+
+		if _, err := tx.ExecContext(ctx, upsertStmt, request.HostID, res.ExecutionID, hostFleetPlatform); err != nil {
+			return ctxerr.Wrapf(ctx, err, "%s host via script update mdm actions", action)
+		}
+		return nil
+	})
+}
+
 func (ds *Datastore) UnlockHostManually(ctx context.Context, hostID uint, hostFleetPlatform string, ts time.Time) error {
 	const stmt = `
 	INSERT INTO host_mdm_actions
@@ -1038,6 +1162,12 @@ func buildHostLockWipeStatusUpdateStmt(refCol string, succeeded bool, joinPart s
 			stmt += fmt.Sprintf("%slock_ref = NULL, %[1]sunlock_ref = NULL, %[1]sunlock_pin = NULL, %[1]swipe_ref = NULL", alias)
 		case "wipe_ref":
 			stmt += fmt.Sprintf("%slock_ref = NULL, %[1]sunlock_ref = NULL, %[1]sunlock_pin = NULL", alias)
+		case "isolate_ref":
+			stmt += fmt.Sprintf("%sunisolate_ref = NULL", alias)
+		case "unisolate_ref":
+			// as for unlock, not being isolated is the default state so a successful
+			// unisolate clears itself as well as the isolate ref.
+			stmt += fmt.Sprintf("%sisolate_ref = NULL, %[1]sunisolate_ref = NULL", alias)
 		}
 	} else {
 		// if the action failed, then we clear the reference to that action itself so
@@ -33,6 +33,7 @@ func TestScripts(t *testing.T) {
 		{"TestUnlockHostViaScript", testUnlockHostViaScript},
 		{"TestLockUnlockWipeViaScripts", testLockUnlockWipeViaScripts},
 		{"TestLockUnlockManually", testLockUnlockManually},
+		{"TestIsolateUnisolateViaScripts", testIsolateUnisolateViaScripts},
 		{"TestInsertScriptContents", testInsertScriptContents},
 		{"TestCleanupUnusedScriptContents", testCleanupUnusedScriptContents},
 	}
@@ -1077,6 +1078,111 @@ func testLockUnlockManually(t *testing.T, ds *Datastore) {
 	require.WithinDuration(t, today, status.UnlockRequestedAt, 1*time.Second)
 }
 
+func testIsolateUnisolateViaScripts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+
+	for i, platform := range []string{"darwin", "windows", "linux"} {
+		host := &fleet.Host{ID: uint(i + 1), Platform: platform, UUID: "uuid"}
+
+		t.Run(platform, func(t *testing.T) {
+			status, err := ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, false, false, false)
+
+			// a lock is requested, which is independent of the isolation (macOS
+			// hosts are locked via MDM, not scripts)
+			if platform != "darwin" {
+				err = ds.LockHostViaScript(ctx, &fleet.HostScriptRequestPayload{
+					HostID:         host.ID,
+					ScriptContents: "lock",
+					UserID:         &user.ID,
+				}, platform)
+				require.NoError(t, err)
+			}
+
+			// record a request to isolate the host
+			err = ds.IsolateHostViaScript(ctx, &fleet.HostScriptRequestPayload{
+				HostID:         host.ID,
+				ScriptContents: "isolate",
+				UserID:         &user.ID,
+			}, platform)
+			require.NoError(t, err)
+
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, false, true, false)
+
+			// simulate a failed result for the isolate script execution
+			_, err = ds.SetHostScriptExecutionResult(ctx, &fleet.HostScriptResultPayload{
+				HostID:      host.ID,
+				ExecutionID: status.IsolateScript.ExecutionID,
+				ExitCode:    1,
+			})
+			require.NoError(t, err)
+
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, false, false, false)
+
+			// record another request and simulate a successful result
+			err = ds.IsolateHostViaScript(ctx, &fleet.HostScriptRequestPayload{
+				HostID:         host.ID,
+				ScriptContents: "isolate",
+				UserID:         &user.ID,
+			}, platform)
+			require.NoError(t, err)
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			_, err = ds.SetHostScriptExecutionResult(ctx, &fleet.HostScriptResultPayload{
+				HostID:      host.ID,
+				ExecutionID: status.IsolateScript.ExecutionID,
+				ExitCode:    0,
+			})
+			require.NoError(t, err)
+
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, true, false, false)
+
+			// record a request to unisolate the host, it is still isolated until
+			// the script succeeds
+			err = ds.UnisolateHostViaScript(ctx, &fleet.HostScriptRequestPayload{
+				HostID:         host.ID,
+				ScriptContents: "unisolate",
+				UserID:         &user.ID,
+			}, platform)
+			require.NoError(t, err)
+
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, true, false, true)
+
+			_, err = ds.SetHostScriptExecutionResult(ctx, &fleet.HostScriptResultPayload{
+				HostID:      host.ID,
+				ExecutionID: status.UnisolateScript.ExecutionID,
+				ExitCode:    0,
+			})
+			require.NoError(t, err)
+
+			status, err = ds.GetHostLockWipeStatus(ctx, host)
+			require.NoError(t, err)
+			checkIsolationState(t, status, false, false, false)
+			require.Nil(t, status.IsolateScript)
+			require.Nil(t, status.UnisolateScript)
+
+			// the lock request was not affected
+			require.Equal(t, platform != "darwin", status.IsPendingLock())
+		})
+	}
+}
+
+func checkIsolationState(t *testing.T, status *fleet.HostLockWipeStatus, isolated, pendingIsolate, pendingUnisolate bool) {
+	require.Equal(t, isolated, status.IsIsolated(), "isolated")
+	require.Equal(t, pendingIsolate, status.IsPendingIsolate(), "pending isolate")
+	require.Equal(t, pendingUnisolate, status.IsPendingUnisolate(), "pending unisolate")
+}
+
 func checkLockWipeState(t *testing.T, status *fleet.HostLockWipeStatus, unlocked, locked, wiped, pendingUnlock, pendingLock, pendingWipe bool) {
 	require.Equal(t, unlocked, status.IsUnlocked(), "unlocked")
 	require.Equal(t, locked, status.IsLocked(), "locked")
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const secretVariableSelectColumns = `
+	id,
+	name,
+	created_at,
+	updated_at
+`
+
+func (ds *Datastore) NewSecretVariable(ctx context.Context, secret fleet.SecretVariablePayload) (*fleet.SecretVariable, error) {
+	encrypted, err := encrypt([]byte(secret.Value), ds.serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "encrypting secret variable")
+	}
+
+	const stmt = `INSERT INTO secret_variables (name, value) VALUES (?, ?)`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, secret.Name, encrypted)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("SecretVariable", secret.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "inserting secret variable")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return ds.secretVariableByID(ctx, ds.writer(ctx), uint(id))
+}
+
+func (ds *Datastore) UpsertSecretVariables(ctx context.Context, secrets []fleet.SecretVariablePayload) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	const stmt = `
+		INSERT INTO secret_variables (name, value)
+		VALUES %s
+		ON DUPLICATE KEY UPDATE value = VALUES(value)
+	`
+	args := make([]any, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		encrypted, err := encrypt([]byte(secret.Value), ds.serverPrivateKey)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "encrypting secret variable")
+		}
+		args = append(args, secret.Name, encrypted)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?),", len(secrets)), ",")
+	if _, err := ds.writer(ctx).ExecContext(ctx, fmt.Sprintf(stmt, values), args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "upserting secret variables")
+	}
+	return nil
+}
+
+func (ds *Datastore) SecretVariable(ctx context.Context, id uint) (*fleet.SecretVariable, error) {
+	return ds.secretVariableByID(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) secretVariableByID(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.SecretVariable, error) {
+	stmt := `SELECT ` + secretVariableSelectColumns + ` FROM secret_variables WHERE id = ?`
+
+	var secret fleet.SecretVariable
+	if err := sqlx.GetContext(ctx, q, &secret, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("SecretVariable").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting secret variable by id")
+	}
+	return &secret, nil
+}
+
+func (ds *Datastore) ListSecretVariables(ctx context.Context) ([]*fleet.SecretVariable, error) {
+	stmt := `SELECT ` + secretVariableSelectColumns + ` FROM secret_variables ORDER BY name`
+
+	var secrets []*fleet.SecretVariable
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &secrets, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing secret variables")
+	}
+	return secrets, nil
+}
+
+func (ds *Datastore) GetSecretVariables(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	stmt, args, err := sqlx.In(`SELECT `+secretVariableSelectColumns+`, value FROM secret_variables WHERE name IN (?) ORDER BY name`, names)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "building secret variables query")
+	}
+
+	var rows []struct {
+		fleet.SecretVariable
+		Encrypted []byte `db:"value"`
+	}
+	// read from the primary so that a secret variable created just before is
+	// always found.
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &rows, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting secret variables")
+	}
+
+	secrets := make([]*fleet.SecretVariable, 0, len(rows))
+	for _, row := range rows {
+		decrypted, err := decrypt(row.Encrypted, ds.serverPrivateKey)
+		if err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "decrypting secret variable %s", row.Name)
+		}
+		secret := row.SecretVariable
+		secret.Value = string(decrypted)
+		secrets = append(secrets, &secret)
+	}
+	return secrets, nil
+}
+
+func (ds *Datastore) DeleteSecretVariable(ctx context.Context, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM secret_variables WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "deleting secret variable")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("SecretVariable").WithID(id))
+	}
+	return nil
+}
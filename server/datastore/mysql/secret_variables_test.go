@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretVariables(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testSecretVariablesCRUD},
+		{"Upsert", testSecretVariablesUpsert},
+		{"MissingPrivateKey", testSecretVariablesMissingPrivateKey},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testSecretVariablesCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	sv1, err := ds.NewSecretVariable(ctx, fleet.SecretVariablePayload{Name: "API_KEY", Value: "abc"})
+	require.NoError(t, err)
+	require.NotZero(t, sv1.ID)
+	require.Equal(t, "API_KEY", sv1.Name)
+	require.Empty(t, sv1.Value)
+
+	_, err = ds.NewSecretVariable(ctx, fleet.SecretVariablePayload{Name: "API_KEY", Value: "def"})
+	require.Error(t, err)
+	var aeErr fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aeErr)
+
+	sv2, err := ds.NewSecretVariable(ctx, fleet.SecretVariablePayload{Name: "TOKEN", Value: "xyz"})
+	require.NoError(t, err)
+
+	// the value is not stored in plain text
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		var raw []byte
+		if err := sqlx.GetContext(ctx, q, &raw, `SELECT value FROM secret_variables WHERE id = ?`, sv1.ID); err != nil {
+			return err
+		}
+		require.NotContains(t, string(raw), "abc")
+		return nil
+	})
+
+	got, err := ds.SecretVariable(ctx, sv2.ID)
+	require.NoError(t, err)
+	require.Equal(t, "TOKEN", got.Name)
+	require.Empty(t, got.Value)
+
+	list, err := ds.ListSecretVariables(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "API_KEY", list[0].Name)
+	require.Equal(t, "TOKEN", list[1].Name)
+	require.Empty(t, list[0].Value)
+
+	values, err := ds.GetSecretVariables(ctx, []string{"TOKEN", "API_KEY", "NO_SUCH"})
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	require.Equal(t, "API_KEY", values[0].Name)
+	require.Equal(t, "abc", values[0].Value)
+	require.Equal(t, "TOKEN", values[1].Name)
+	require.Equal(t, "xyz", values[1].Value)
+
+	values, err = ds.GetSecretVariables(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	require.NoError(t, ds.DeleteSecretVariable(ctx, sv1.ID))
+	err = ds.DeleteSecretVariable(ctx, sv1.ID)
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.SecretVariable(ctx, sv1.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testSecretVariablesUpsert(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	require.NoError(t, ds.UpsertSecretVariables(ctx, nil))
+	require.NoError(t, ds.UpsertSecretVariables(ctx, []fleet.SecretVariablePayload{
+		{Name: "A", Value: "1"},
+		{Name: "B", Value: "2"},
+	}))
+	require.NoError(t, ds.UpsertSecretVariables(ctx, []fleet.SecretVariablePayload{
+		{Name: "B", Value: "3"},
+		{Name: "C", Value: "4"},
+	}))
+
+	values, err := ds.GetSecretVariables(ctx, []string{"A", "B", "C"})
+	require.NoError(t, err)
+	require.Len(t, values, 3)
+	got := make(map[string]string, len(values))
+	for _, v := range values {
+		got[v.Name] = v.Value
+	}
+	require.Equal(t, map[string]string{"A": "1", "B": "3", "C": "4"}, got)
+}
+
+func testSecretVariablesMissingPrivateKey(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	_, err := ds.NewSecretVariable(ctx, fleet.SecretVariablePayload{Name: "A", Value: "1"})
+	require.NoError(t, err)
+
+	key := ds.serverPrivateKey
+	ds.serverPrivateKey = ""
+	t.Cleanup(func() { ds.serverPrivateKey = key })
+
+	_, err = ds.NewSecretVariable(ctx, fleet.SecretVariablePayload{Name: "B", Value: "2"})
+	require.ErrorContains(t, err, "private key")
+	_, err = ds.GetSecretVariables(ctx, []string{"A"})
+	require.ErrorContains(t, err, "private key")
+
+	// a different key cannot decrypt the value
+	ds.serverPrivateKey = "ThisIsADifferentPrivateKeyOf32By"
+	_, err = ds.GetSecretVariables(ctx, []string{"A"})
+	require.ErrorContains(t, err, "decrypt")
+}
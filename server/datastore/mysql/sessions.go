@@ -3,6 +3,9 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -68,15 +71,17 @@ func (ds *Datastore) ListSessionsForUser(ctx context.Context, id uint) ([]*fleet
 	return sessions, nil
 }
 
-func (ds *Datastore) NewSession(ctx context.Context, userID uint, sessionKey string) (*fleet.Session, error) {
+func (ds *Datastore) NewSession(ctx context.Context, userID uint, sessionKey, ipAddress, userAgent string) (*fleet.Session, error) {
 	sqlStatement := `
 		INSERT INTO sessions (
 			user_id,
-			` + "`key`" + `
+			` + "`key`" + `,
+			ip_address,
+			user_agent
 		)
-		VALUES(?,?)
+		VALUES(?,?,?,?)
 	`
-	result, err := ds.writer(ctx).ExecContext(ctx, sqlStatement, userID, sessionKey)
+	result, err := ds.writer(ctx).ExecContext(ctx, sqlStatement, userID, sessionKey, ipAddress, userAgent)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "inserting session")
 	}
@@ -125,3 +130,32 @@ func (ds *Datastore) MarkSessionAccessed(ctx context.Context, session *fleet.Ses
 	}
 	return nil
 }
+
+func (ds *Datastore) CleanupExpiredSessions(ctx context.Context, idleTimeout, maxLifetime time.Duration) (int64, error) {
+	var conds []string
+	var args []interface{}
+	if idleTimeout > 0 {
+		conds = append(conds, `s.accessed_at < DATE_SUB(NOW(), INTERVAL ? SECOND)`)
+		args = append(args, int(idleTimeout.Seconds()))
+	}
+	if maxLifetime > 0 {
+		conds = append(conds, `s.created_at < DATE_SUB(NOW(), INTERVAL ? SECOND)`)
+		args = append(args, int(maxLifetime.Seconds()))
+	}
+	if len(conds) == 0 {
+		return 0, nil
+	}
+
+	// the sessions of API-only users never expire, see validateSession in the
+	// service.
+	stmt := fmt.Sprintf(`
+		DELETE s FROM sessions s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE COALESCE(u.api_only, 0) = 0 AND (%s)`, strings.Join(conds, " OR "))
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "deleting expired sessions")
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,6 +21,7 @@ func TestSessions(t *testing.T) {
 		fn   func(t *testing.T, ds *Datastore)
 	}{
 		{"Getters", testSessionsGetters},
+		{"CleanupExpired", testSessionsCleanupExpired},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -37,7 +39,7 @@ func testSessionsGetters(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 
-	session, err := ds.NewSession(context.Background(), user.ID, "somekey")
+	session, err := ds.NewSession(context.Background(), user.ID, "somekey", "", "")
 	require.NoError(t, err)
 	require.NotZero(t, session.ID)
 
@@ -53,8 +55,10 @@ func testSessionsGetters(t *testing.T, ds *Datastore) {
 	require.NotNil(t, gotByKey.APIOnly)
 	assert.False(t, *gotByKey.APIOnly)
 
-	newSession, err := ds.NewSession(context.Background(), user.ID, "somekey2")
+	newSession, err := ds.NewSession(context.Background(), user.ID, "somekey2", "10.0.0.1", "fleetctl/4.48.0")
 	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", newSession.IPAddress)
+	assert.Equal(t, "fleetctl/4.48.0", newSession.UserAgent)
 
 	sessions, err := ds.ListSessionsForUser(context.Background(), user.ID)
 	require.NoError(t, err)
@@ -78,7 +82,7 @@ func testSessionsGetters(t *testing.T, ds *Datastore) {
 	require.NoError(t, ds.DestroyAllSessionsForUser(context.Background(), user.ID))
 
 	// session for a non-existing user
-	newSession, err = ds.NewSession(context.Background(), user.ID+1, "someotherkey")
+	newSession, err = ds.NewSession(context.Background(), user.ID+1, "someotherkey", "", "")
 	require.NoError(t, err)
 
 	gotByKey, err = ds.SessionByKey(context.Background(), newSession.Key)
@@ -99,7 +103,7 @@ func testSessionsGetters(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 
 	// session for an api user
-	apiSession, err := ds.NewSession(context.Background(), apiUser.ID, "someapikey")
+	apiSession, err := ds.NewSession(context.Background(), apiUser.ID, "someapikey", "", "")
 	require.NoError(t, err)
 
 	gotByKey, err = ds.SessionByKey(context.Background(), apiSession.Key)
@@ -114,3 +118,61 @@ func testSessionsGetters(t *testing.T, ds *Datastore) {
 	require.NotNil(t, gotByKey.APIOnly)
 	assert.True(t, *gotByKey.APIOnly)
 }
+
+func testSessionsCleanupExpired(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	user, err := ds.NewUser(ctx, &fleet.User{
+		Password:   []byte("supersecret"),
+		Email:      "user@example.com",
+		GlobalRole: ptr.String(fleet.RoleObserver),
+	})
+	require.NoError(t, err)
+	apiUser, err := ds.NewUser(ctx, &fleet.User{
+		Password:   []byte("supersecret"),
+		Email:      "api@example.com",
+		GlobalRole: ptr.String(fleet.RoleObserver),
+		APIOnly:    true,
+	})
+	require.NoError(t, err)
+
+	idle, err := ds.NewSession(ctx, user.ID, "idle", "", "")
+	require.NoError(t, err)
+	old, err := ds.NewSession(ctx, user.ID, "old", "", "")
+	require.NoError(t, err)
+	active, err := ds.NewSession(ctx, user.ID, "active", "", "")
+	require.NoError(t, err)
+	api, err := ds.NewSession(ctx, apiUser.ID, "api", "", "")
+	require.NoError(t, err)
+
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		if _, err := q.ExecContext(ctx, `UPDATE sessions SET accessed_at = DATE_SUB(NOW(), INTERVAL 2 DAY) WHERE id IN (?, ?)`, idle.ID, api.ID); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `UPDATE sessions SET created_at = DATE_SUB(NOW(), INTERVAL 40 DAY) WHERE id = ?`, old.ID)
+		return err
+	})
+
+	// no expiration
+	n, err := ds.CleanupExpiredSessions(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = ds.CleanupExpiredSessions(ctx, 24*time.Hour, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	_, err = ds.SessionByID(ctx, idle.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	n, err = ds.CleanupExpiredSessions(ctx, 24*time.Hour, 30*24*time.Hour)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	_, err = ds.SessionByID(ctx, old.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the sessions of API-only users never expire
+	for _, id := range []uint{active.ID, api.ID} {
+		_, err = ds.SessionByID(ctx, id)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,246 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// sharedProfileTargetsHostCondition returns the SQL condition that is true if
+// the configuration profile (alias p, of the Apple or Windows profiles table)
+// is a "no team" profile shared with all teams that applies to the host
+// (alias h): the host is in a team that did not opt out of the profile and
+// that does not have a profile of its own with the same identifier (Apple) or
+// name, which takes precedence over the shared profile.
+func sharedProfileTargetsHostCondition(platform, p, h string) string {
+	table, uuidColumn, sameProfile := "mdm_windows_configuration_profiles", "windows_profile_uuid", "tp.name = %[1]s.name"
+	if platform == "darwin" {
+		table, uuidColumn, sameProfile = "mdm_apple_configuration_profiles", "apple_profile_uuid", "(tp.identifier = %[1]s.identifier OR tp.name = %[1]s.name)"
+	}
+	return fmt.Sprintf(`(
+		%[1]s.team_id = 0 AND %[1]s.shared_with_all_teams = 1 AND %[2]s.team_id IS NOT NULL AND
+		NOT EXISTS (
+			SELECT 1 FROM team_shared_library_opt_outs tslo
+			WHERE tslo.team_id = %[2]s.team_id AND tslo.`+uuidColumn+` = %[1]s.profile_uuid
+		) AND
+		NOT EXISTS (
+			SELECT 1 FROM `+table+` tp
+			WHERE tp.team_id = %[2]s.team_id AND `+sameProfile+`
+		)
+	)`, p, h)
+}
+
+// profileTargetsHostTeamCondition returns the SQL condition of the JOIN
+// between the Apple or Windows configuration profiles (alias p) and the hosts
+// (alias h) that the profiles apply to, i.e. the profiles of the team of the
+// host (or no team) and the profiles shared with all teams.
+func profileTargetsHostTeamCondition(platform, p, h string) string {
+	return fmt.Sprintf(`(%[2]s.team_id = %[1]s.team_id OR (%[2]s.team_id IS NULL AND %[1]s.team_id = 0) OR %[3]s)`,
+		p, h, sharedProfileTargetsHostCondition(platform, p, h))
+}
+
+func (ds *Datastore) SetScriptSharedWithAllTeams(ctx context.Context, scriptID uint, shared bool) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		script, err := ds.getScriptDB(ctx, tx, scriptID)
+		if err != nil {
+			return err
+		}
+		if script.TeamID != nil {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("script_id", "Only scripts of no team can be shared with all teams."))
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE scripts SET shared_with_all_teams = ? WHERE id = ?`, shared, scriptID); err != nil {
+			return ctxerr.Wrap(ctx, err, "update script shared with all teams")
+		}
+		if !shared {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM team_shared_library_opt_outs WHERE script_id = ?`, scriptID); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete script opt-outs")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) SetMDMConfigProfileSharedWithAllTeams(ctx context.Context, profileUUID string, shared bool) error {
+	var table, uuidColumn, entity string
+	switch {
+	case strings.HasPrefix(profileUUID, fleet.MDMAppleProfileUUIDPrefix):
+		table, uuidColumn, entity = "mdm_apple_configuration_profiles", "apple_profile_uuid", "MDMAppleConfigProfile"
+	case strings.HasPrefix(profileUUID, fleet.MDMWindowsProfileUUIDPrefix):
+		table, uuidColumn, entity = "mdm_windows_configuration_profiles", "windows_profile_uuid", "MDMWindowsConfigProfile"
+	default:
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", "Only Apple and Windows configuration profiles can be shared with all teams."))
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var teamID uint
+		if err := sqlx.GetContext(ctx, tx, &teamID, fmt.Sprintf(`SELECT team_id FROM %s WHERE profile_uuid = ?`, table), profileUUID); err != nil {
+			if err == sql.ErrNoRows {
+				return ctxerr.Wrap(ctx, notFound(entity).WithName(profileUUID))
+			}
+			return ctxerr.Wrap(ctx, err, "get profile team")
+		}
+		if teamID != 0 {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", "Only configuration profiles of no team can be shared with all teams."))
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET shared_with_all_teams = ? WHERE profile_uuid = ?`, table), shared, profileUUID); err != nil {
+			return ctxerr.Wrap(ctx, err, "update profile shared with all teams")
+		}
+		if !shared {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM team_shared_library_opt_outs WHERE %s = ?`, uuidColumn), profileUUID); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete profile opt-outs")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) GetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error) {
+	const stmt = `
+SELECT
+	script_id,
+	COALESCE(apple_profile_uuid, windows_profile_uuid) AS profile_uuid
+FROM
+	team_shared_library_opt_outs
+WHERE
+	team_id = ?
+ORDER BY
+	id`
+
+	var rows []struct {
+		ScriptID    *uint   `db:"script_id"`
+		ProfileUUID *string `db:"profile_uuid"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, teamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select team shared library opt-outs")
+	}
+
+	optOuts := &fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{}, ProfileUUIDs: []string{}}
+	for _, r := range rows {
+		if r.ScriptID != nil {
+			optOuts.ScriptIDs = append(optOuts.ScriptIDs, *r.ScriptID)
+		}
+		if r.ProfileUUID != nil {
+			optOuts.ProfileUUIDs = append(optOuts.ProfileUUIDs, *r.ProfileUUID)
+		}
+	}
+	return optOuts, nil
+}
+
+func (ds *Datastore) SetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) error {
+	var appleUUIDs, windowsUUIDs []string
+	for _, uuid := range optOuts.ProfileUUIDs {
+		switch {
+		case strings.HasPrefix(uuid, fleet.MDMAppleProfileUUIDPrefix):
+			appleUUIDs = append(appleUUIDs, uuid)
+		case strings.HasPrefix(uuid, fleet.MDMWindowsProfileUUIDPrefix):
+			windowsUUIDs = append(windowsUUIDs, uuid)
+		default:
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuids", fmt.Sprintf("Profile %s is not shared with all teams.", uuid)))
+		}
+	}
+
+	// checkShared fails if one of the items is not shared with all teams.
+	checkShared := func(tx sqlx.ExtContext, table, idColumn, argName string, ids any, count int) error {
+		if count == 0 {
+			return nil
+		}
+		stmt, args, err := sqlx.In(fmt.Sprintf(`SELECT COUNT(DISTINCT %s) FROM %s WHERE %s IN (?) AND shared_with_all_teams = 1`, idColumn, table, idColumn), ids)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build shared items query")
+		}
+		var shared int
+		if err := sqlx.GetContext(ctx, tx, &shared, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "count shared items")
+		}
+		if shared != count {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError(argName, "All the items must be shared with all teams."))
+		}
+		return nil
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if err := checkShared(tx, "scripts", "id", "script_ids", optOuts.ScriptIDs, countDistinct(optOuts.ScriptIDs)); err != nil {
+			return err
+		}
+		if err := checkShared(tx, "mdm_apple_configuration_profiles", "profile_uuid", "profile_uuids", appleUUIDs, countDistinct(appleUUIDs)); err != nil {
+			return err
+		}
+		if err := checkShared(tx, "mdm_windows_configuration_profiles", "profile_uuid", "profile_uuids", windowsUUIDs, countDistinct(windowsUUIDs)); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM team_shared_library_opt_outs WHERE team_id = ?`, teamID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete team shared library opt-outs")
+		}
+
+		var placeholders []string
+		var args []any
+		for _, id := range optOuts.ScriptIDs {
+			placeholders = append(placeholders, "(?, ?, NULL, NULL)")
+			args = append(args, teamID, id)
+		}
+		for _, uuid := range appleUUIDs {
+			placeholders = append(placeholders, "(?, NULL, ?, NULL)")
+			args = append(args, teamID, uuid)
+		}
+		for _, uuid := range windowsUUIDs {
+			placeholders = append(placeholders, "(?, NULL, NULL, ?)")
+			args = append(args, teamID, uuid)
+		}
+		if len(placeholders) == 0 {
+			return nil
+		}
+		stmt := `
+INSERT IGNORE INTO team_shared_library_opt_outs
+	(team_id, script_id, apple_profile_uuid, windows_profile_uuid)
+VALUES ` + strings.Join(placeholders, ", ")
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			if isChildForeignKeyError(err) {
+				return ctxerr.Wrap(ctx, foreignKey("team_shared_library_opt_outs", fmt.Sprintf("team_id=%d", teamID)))
+			}
+			return ctxerr.Wrap(ctx, err, "insert team shared library opt-outs")
+		}
+		return nil
+	})
+}
+
+func countDistinct[T comparable](values []T) int {
+	seen := make(map[T]bool, len(values))
+	for _, v := range values {
+		seen[v] = true
+	}
+	return len(seen)
+}
+
+// sharedScriptsCondition returns the SQL condition that is true if the script
+// (alias s) is a "no team" script shared with all teams that is available to
+// the team, i.e. the team does not have a script of its own with the same
+// name, which takes precedence over the shared script. If excludeOptedOut is
+// true, the scripts that the team opted out of are excluded. It returns the
+// arguments of the condition's placeholders.
+func sharedScriptsCondition(s string, teamID uint, excludeOptedOut bool) (string, []any) {
+	cond := fmt.Sprintf(`(
+		%[1]s.global_or_team_id = 0 AND %[1]s.shared_with_all_teams = 1 AND
+		NOT EXISTS (
+			SELECT 1 FROM scripts ts
+			WHERE ts.global_or_team_id = ? AND ts.name = %[1]s.name
+		)`, s)
+	args := []any{teamID}
+	if excludeOptedOut {
+		cond += fmt.Sprintf(` AND
+		NOT EXISTS (
+			SELECT 1 FROM team_shared_library_opt_outs tslo
+			WHERE tslo.team_id = ? AND tslo.script_id = %s.id
+		)`, s)
+		args = append(args, teamID)
+	}
+	return cond + `
+	)`, args
+}
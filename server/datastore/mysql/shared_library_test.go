@@ -0,0 +1,266 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedLibrary(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"SharedScripts", testSharedScripts},
+		{"SharedProfiles", testSharedProfiles},
+		{"TeamSharedLibraryOptOuts", testTeamSharedLibraryOptOuts},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testSharedScripts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tm1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	tm2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	a, err := ds.NewScript(ctx, &fleet.Script{Name: "a.sh", ScriptContents: "echo a"})
+	require.NoError(t, err)
+	b, err := ds.NewScript(ctx, &fleet.Script{Name: "b.sh", ScriptContents: "echo b"})
+	require.NoError(t, err)
+	// team 1 has its own "b.sh" that takes precedence over the shared one
+	tmB, err := ds.NewScript(ctx, &fleet.Script{Name: "b.sh", TeamID: &tm1.ID, ScriptContents: "echo team b"})
+	require.NoError(t, err)
+
+	// team scripts cannot be shared
+	err = ds.SetScriptSharedWithAllTeams(ctx, tmB.ID, true)
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	err = ds.SetScriptSharedWithAllTeams(ctx, 999, true)
+	require.True(t, fleet.IsNotFound(err))
+
+	listNames := func(teamID *uint) map[string]*fleet.Script {
+		scripts, _, err := ds.ListScripts(ctx, teamID, fleet.ListOptions{})
+		require.NoError(t, err)
+		byName := make(map[string]*fleet.Script, len(scripts))
+		for _, s := range scripts {
+			byName[s.Name] = s
+		}
+		return byName
+	}
+
+	// nothing shared yet
+	require.Len(t, listNames(&tm1.ID), 1)
+	require.Len(t, listNames(&tm2.ID), 0)
+
+	require.NoError(t, ds.SetScriptSharedWithAllTeams(ctx, a.ID, true))
+	require.NoError(t, ds.SetScriptSharedWithAllTeams(ctx, b.ID, true))
+
+	script, err := ds.Script(ctx, a.ID)
+	require.NoError(t, err)
+	require.True(t, script.SharedWithAllTeams)
+
+	// no team lists all its scripts, without opt-out information
+	global := listNames(nil)
+	require.Len(t, global, 2)
+	require.True(t, global["a.sh"].SharedWithAllTeams)
+	require.Nil(t, global["a.sh"].OptedOut)
+
+	team1 := listNames(&tm1.ID)
+	require.Len(t, team1, 2)
+	require.Equal(t, tmB.ID, team1["b.sh"].ID)
+	require.Nil(t, team1["b.sh"].OptedOut)
+	require.Equal(t, a.ID, team1["a.sh"].ID)
+	require.NotNil(t, team1["a.sh"].OptedOut)
+	require.False(t, *team1["a.sh"].OptedOut)
+
+	team2 := listNames(&tm2.ID)
+	require.Len(t, team2, 2)
+	require.Equal(t, b.ID, team2["b.sh"].ID)
+
+	id, err := ds.GetScriptIDByName(ctx, "b.sh", &tm1.ID)
+	require.NoError(t, err)
+	require.Equal(t, tmB.ID, id)
+	id, err = ds.GetScriptIDByName(ctx, "b.sh", &tm2.ID)
+	require.NoError(t, err)
+	require.Equal(t, b.ID, id)
+
+	host := test.NewHost(t, ds, "host1", "10.0.0.1", "1", "1", time.Now())
+	host.Platform = "darwin"
+	require.NoError(t, ds.AddHostsToTeam(ctx, &tm2.ID, []uint{host.ID}))
+	details, _, err := ds.GetHostScriptDetails(ctx, host.ID, &tm2.ID, fleet.ListOptions{}, host.Platform)
+	require.NoError(t, err)
+	require.Len(t, details, 2)
+
+	// team 2 opts out of "a.sh"
+	require.NoError(t, ds.SetTeamSharedLibraryOptOuts(ctx, tm2.ID, fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{a.ID}}))
+
+	team2 = listNames(&tm2.ID)
+	require.Len(t, team2, 2)
+	require.True(t, *team2["a.sh"].OptedOut)
+	_, err = ds.GetScriptIDByName(ctx, "a.sh", &tm2.ID)
+	require.True(t, fleet.IsNotFound(err))
+	details, _, err = ds.GetHostScriptDetails(ctx, host.ID, &tm2.ID, fleet.ListOptions{}, host.Platform)
+	require.NoError(t, err)
+	require.Len(t, details, 1)
+	require.Equal(t, "b.sh", details[0].Name)
+
+	// unsharing the script removes the opt-outs
+	require.NoError(t, ds.SetScriptSharedWithAllTeams(ctx, a.ID, false))
+	optOuts, err := ds.GetTeamSharedLibraryOptOuts(ctx, tm2.ID)
+	require.NoError(t, err)
+	require.Empty(t, optOuts.ScriptIDs)
+	team2 = listNames(&tm2.ID)
+	require.Len(t, team2, 1)
+	require.Contains(t, team2, "b.sh")
+}
+
+func testSharedProfiles(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	opts := fleet.ListOptions{OrderKey: "name"}
+
+	tm1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	tm2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	appleProf, err := ds.NewMDMAppleConfigProfile(ctx, *configProfileForTest(t, "shared-mac", "com.example.shared", "a"))
+	require.NoError(t, err)
+	winProf, err := ds.NewMDMWindowsConfigProfile(ctx, fleet.MDMWindowsConfigProfile{Name: "shared-win", SyncML: []byte("<Replace></Replace>")})
+	require.NoError(t, err)
+	// team 1 overrides the shared Apple profile with its own
+	tmProf, err := ds.NewMDMAppleConfigProfile(ctx, *generateCP("team-mac", "com.example.shared", tm1.ID))
+	require.NoError(t, err)
+
+	err = ds.SetMDMConfigProfileSharedWithAllTeams(ctx, tmProf.ProfileUUID, true)
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	err = ds.SetMDMConfigProfileSharedWithAllTeams(ctx, fleet.MDMAppleProfileUUIDPrefix+"no-such-profile", true)
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.SetMDMConfigProfileSharedWithAllTeams(ctx, appleProf.ProfileUUID, true))
+	require.NoError(t, ds.SetMDMConfigProfileSharedWithAllTeams(ctx, winProf.ProfileUUID, true))
+
+	// the shared profiles are listed for the teams
+	profs, _, err := ds.ListMDMConfigProfiles(ctx, &tm2.ID, opts)
+	require.NoError(t, err)
+	require.Len(t, profs, 2)
+	for _, p := range profs {
+		require.True(t, p.SharedWithAllTeams)
+		require.NotNil(t, p.OptedOut)
+		require.False(t, *p.OptedOut)
+	}
+	profs, _, err = ds.ListMDMConfigProfiles(ctx, nil, opts)
+	require.NoError(t, err)
+	require.Len(t, profs, 2)
+	for _, p := range profs {
+		require.Nil(t, p.OptedOut)
+	}
+
+	// a host of each team gets the shared profiles, except if overridden
+	newMacHost := func(name string, teamID uint) *fleet.Host {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:      name,
+			OsqueryHostID: ptr.String(name),
+			NodeKey:       ptr.String(name),
+			UUID:          name,
+			TeamID:        &teamID,
+			Platform:      "darwin",
+		})
+		require.NoError(t, err)
+		nanoEnroll(t, ds, h, false)
+		return h
+	}
+	h1 := newMacHost("mac-team1", tm1.ID)
+	h2 := newMacHost("mac-team2", tm2.ID)
+
+	toInstall, err := ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	byHost := make(map[string][]string)
+	for _, p := range toInstall {
+		byHost[p.HostUUID] = append(byHost[p.HostUUID], p.ProfileUUID)
+	}
+	require.Equal(t, []string{tmProf.ProfileUUID}, byHost[h1.UUID])
+	require.Equal(t, []string{appleProf.ProfileUUID}, byHost[h2.UUID])
+
+	expected, err := ds.GetHostMDMProfilesExpectedForVerification(ctx, h2)
+	require.NoError(t, err)
+	require.Contains(t, expected, "com.example.shared")
+
+	// team 2 opts out of the Apple profile
+	require.NoError(t, ds.SetTeamSharedLibraryOptOuts(ctx, tm2.ID, fleet.TeamSharedLibraryOptOuts{ProfileUUIDs: []string{appleProf.ProfileUUID}}))
+	toInstall, err = ds.ListMDMAppleProfilesToInstall(ctx)
+	require.NoError(t, err)
+	for _, p := range toInstall {
+		require.NotEqual(t, h2.UUID, p.HostUUID)
+	}
+	expected, err = ds.GetHostMDMProfilesExpectedForVerification(ctx, h2)
+	require.NoError(t, err)
+	require.Empty(t, expected)
+
+	profs, _, err = ds.ListMDMConfigProfiles(ctx, &tm2.ID, opts)
+	require.NoError(t, err)
+	require.Len(t, profs, 2)
+	for _, p := range profs {
+		require.Equal(t, p.ProfileUUID == appleProf.ProfileUUID, *p.OptedOut)
+	}
+}
+
+func testTeamSharedLibraryOptOuts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tm, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	shared, err := ds.NewScript(ctx, &fleet.Script{Name: "shared.sh", ScriptContents: "echo"})
+	require.NoError(t, err)
+	require.NoError(t, ds.SetScriptSharedWithAllTeams(ctx, shared.ID, true))
+	notShared, err := ds.NewScript(ctx, &fleet.Script{Name: "not-shared.sh", ScriptContents: "echo"})
+	require.NoError(t, err)
+	winProf, err := ds.NewMDMWindowsConfigProfile(ctx, fleet.MDMWindowsConfigProfile{Name: "win", SyncML: []byte("<Replace></Replace>")})
+	require.NoError(t, err)
+	require.NoError(t, ds.SetMDMConfigProfileSharedWithAllTeams(ctx, winProf.ProfileUUID, true))
+
+	optOuts, err := ds.GetTeamSharedLibraryOptOuts(ctx, tm.ID)
+	require.NoError(t, err)
+	require.Equal(t, &fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{}, ProfileUUIDs: []string{}}, optOuts)
+
+	// only the shared items can be opted out of
+	var iae *fleet.InvalidArgumentError
+	err = ds.SetTeamSharedLibraryOptOuts(ctx, tm.ID, fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{shared.ID, notShared.ID}})
+	require.ErrorAs(t, err, &iae)
+	err = ds.SetTeamSharedLibraryOptOuts(ctx, tm.ID, fleet.TeamSharedLibraryOptOuts{ProfileUUIDs: []string{"zzz"}})
+	require.ErrorAs(t, err, &iae)
+
+	// duplicates are ignored
+	require.NoError(t, ds.SetTeamSharedLibraryOptOuts(ctx, tm.ID, fleet.TeamSharedLibraryOptOuts{
+		ScriptIDs:    []uint{shared.ID, shared.ID},
+		ProfileUUIDs: []string{winProf.ProfileUUID},
+	}))
+	optOuts, err = ds.GetTeamSharedLibraryOptOuts(ctx, tm.ID)
+	require.NoError(t, err)
+	require.Equal(t, []uint{shared.ID}, optOuts.ScriptIDs)
+	require.Equal(t, []string{winProf.ProfileUUID}, optOuts.ProfileUUIDs)
+
+	// the opt-outs are replaced
+	require.NoError(t, ds.SetTeamSharedLibraryOptOuts(ctx, tm.ID, fleet.TeamSharedLibraryOptOuts{}))
+	optOuts, err = ds.GetTeamSharedLibraryOptOuts(ctx, tm.ID)
+	require.NoError(t, err)
+	require.Empty(t, optOuts.ScriptIDs)
+	require.Empty(t, optOuts.ProfileUUIDs)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,9 +28,18 @@ func softwareSliceToMap(softwares []fleet.Software) map[string]fleet.Software {
 }
 
 func (ds *Datastore) UpdateHostSoftware(ctx context.Context, hostID uint, software []fleet.Software) (*fleet.UpdateHostSoftwareDBResult, error) {
+	return ds.UpdateHostSoftwareFromSource(ctx, hostID, fleet.SoftwareInventorySourceOsquery, software)
+}
+
+func (ds *Datastore) UpdateHostSoftwareFromSource(
+	ctx context.Context,
+	hostID uint,
+	source fleet.SoftwareInventorySource,
+	software []fleet.Software,
+) (*fleet.UpdateHostSoftwareDBResult, error) {
 	var result *fleet.UpdateHostSoftwareDBResult
 	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
-		r, err := applyChangesForNewSoftwareDB(ctx, tx, hostID, software, ds.minLastOpenedAtDiff)
+		r, err := applyChangesForNewSoftwareFromSourceDB(ctx, tx, hostID, source, software, ds.minLastOpenedAtDiff)
 		result = r
 		return err
 	})
@@ -48,25 +58,71 @@ func (ds *Datastore) UpdateHostSoftware(ctx context.Context, hostID uint, softwa
 		for _, software := range result.Deleted {
 			deletesHostSoftwareIDs = append(deletesHostSoftwareIDs, software.ID)
 		}
-		slices.Sort(deletesHostSoftwareIDs)
-		if err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
-			stmt := `DELETE FROM software WHERE id IN (?) AND NOT EXISTS (
-				SELECT 1 FROM host_software hsw WHERE hsw.software_id = software.id
-			)`
-			stmt, args, err := sqlx.In(stmt, deletesHostSoftwareIDs)
+		if err := ds.deleteUnusedSoftware(ctx, deletesHostSoftwareIDs); err != nil {
+			return result, err
+		}
+	}
+
+	return result, err
+}
+
+// deleteUnusedSoftware deletes the software with the given IDs that are no
+// longer installed on any host.
+func (ds *Datastore) deleteUnusedSoftware(ctx context.Context, softwareIDs []uint) error {
+	slices.Sort(softwareIDs)
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		stmt := `DELETE FROM software WHERE id IN (?) AND NOT EXISTS (
+			SELECT 1 FROM host_software hsw WHERE hsw.software_id = software.id
+		)`
+		stmt, args, err := sqlx.In(stmt, softwareIDs)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build delete software query")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete software")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) UpdateHostSoftwareUpdatedAt(ctx context.Context, hostID uint) error {
+	return updateSoftwareUpdatedAt(ctx, ds.writer(ctx), hostID)
+}
+
+func (ds *Datastore) DeleteHostSoftwareByBundleIdentifier(ctx context.Context, hostID uint, bundleIdentifier string) error {
+	var softwareIDs []uint
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		softwareIDs = softwareIDs[:0]
+		const selectStmt = `
+			SELECT hs.software_id
+			FROM host_software hs
+			JOIN software s ON s.id = hs.software_id
+			WHERE hs.host_id = ? AND s.bundle_identifier = ?`
+		if err := sqlx.SelectContext(ctx, tx, &softwareIDs, selectStmt, hostID, bundleIdentifier); err != nil {
+			return ctxerr.Wrap(ctx, err, "select host software by bundle identifier")
+		}
+		if len(softwareIDs) == 0 {
+			return nil
+		}
+
+		for _, table := range []string{"host_software_installed_paths", "host_software_inventory_sources", "host_software"} {
+			stmt, args, err := sqlx.In(`DELETE FROM `+table+` WHERE host_id = ? AND software_id IN (?)`, hostID, softwareIDs)
 			if err != nil {
-				return ctxerr.Wrap(ctx, err, "build delete software query")
+				return ctxerr.Wrapf(ctx, err, "build delete %s query", table)
 			}
 			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
-				return ctxerr.Wrap(ctx, err, "delete software")
+				return ctxerr.Wrapf(ctx, err, "delete %s", table)
 			}
-			return nil
-		}); err != nil {
-			return result, err
 		}
+		return updateSoftwareUpdatedAt(ctx, tx, hostID)
+	})
+	if err != nil || len(softwareIDs) == 0 {
+		return err
 	}
 
-	return result, err
+	// like in UpdateHostSoftwareFromSource, the software no longer installed
+	// on any host is deleted in a separate transaction to avoid deadlocks.
+	return ds.deleteUnusedSoftware(ctx, softwareIDs)
 }
 
 func (ds *Datastore) UpdateHostSoftwareInstalledPaths(
@@ -332,21 +388,72 @@ WHERE
 	return softwares, nil
 }
 
-// applyChangesForNewSoftwareDB returns the current host software and the applied mutations: what
-// was inserted and what was deleted
-func applyChangesForNewSoftwareDB(
+// applyChangesForNewSoftwareFromSourceDB resolves the software installed on
+// the host after the source reported the given software (see
+// resolveHostSoftwareInventory), applies it and stores the sources of the
+// software. It returns the current host software and the applied mutations:
+// what was inserted and what was deleted.
+func applyChangesForNewSoftwareFromSourceDB(
 	ctx context.Context,
 	tx sqlx.ExtContext,
 	hostID uint,
+	source fleet.SoftwareInventorySource,
 	software []fleet.Software,
 	minLastOpenedAtDiff time.Duration,
 ) (*fleet.UpdateHostSoftwareDBResult, error) {
-	r := &fleet.UpdateHostSoftwareDBResult{}
-
 	currentSoftware, err := listSoftwareByHostIDShort(ctx, tx, hostID)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "loading current software for host")
 	}
+	storedSources, err := listHostSoftwareInventorySourcesDB(ctx, tx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	softwareIDs := make(map[string]uint, len(currentSoftware))
+	uniqueStrByID := make(map[uint]string, len(currentSoftware))
+	for _, s := range currentSoftware {
+		softwareIDs[s.ToUniqueStr()] = s.ID
+		uniqueStrByID[s.ID] = s.ToUniqueStr()
+	}
+	currentSources := make(map[string]inventorySources)
+	for _, s := range storedSources {
+		key, ok := uniqueStrByID[s.SoftwareID]
+		if !ok {
+			continue
+		}
+		if currentSources[key] == nil {
+			currentSources[key] = make(inventorySources)
+		}
+		currentSources[key][s.Source] = s.ReportedVersion
+	}
+
+	resolved, resolvedSources := resolveHostSoftwareInventory(currentSoftware, currentSources, source, software)
+
+	r, err := applyChangesForNewSoftwareDB(ctx, tx, hostID, currentSoftware, resolved, minLastOpenedAtDiff)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range r.Inserted {
+		softwareIDs[s.ToUniqueStr()] = s.ID
+	}
+	if err := updateHostSoftwareInventorySourcesDB(ctx, tx, hostID, storedSources, resolved, resolvedSources, softwareIDs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// applyChangesForNewSoftwareDB returns the current host software and the applied mutations: what
+// was inserted and what was deleted
+func applyChangesForNewSoftwareDB(
+	ctx context.Context,
+	tx sqlx.ExtContext,
+	hostID uint,
+	currentSoftware []fleet.Software,
+	software []fleet.Software,
+	minLastOpenedAtDiff time.Duration,
+) (*fleet.UpdateHostSoftwareDBResult, error) {
+	r := &fleet.UpdateHostSoftwareDBResult{}
 	r.WasCurrInstalled = currentSoftware
 
 	if nothingChanged(currentSoftware, software, minLastOpenedAtDiff) {
@@ -791,6 +898,17 @@ func selectSoftwareSQL(opts fleet.SoftwareListOptions) (string, []interface{}, e
 			)
 	}
 
+	// keyset pagination is only supported on the software ID (as used by
+	// StreamSoftware), as the other order keys are not unique.
+	if after := opts.ListOptions.After; after != "" && opts.ListOptions.OrderKey == "id" {
+		afterID, _ := strconv.ParseUint(after, 10, 64)
+		if opts.ListOptions.OrderDirection == fleet.OrderDescending {
+			ds = ds.Where(goqu.I("s.id").Lt(afterID))
+		} else {
+			ds = ds.Where(goqu.I("s.id").Gt(afterID))
+		}
+	}
+
 	ds = ds.GroupBy(
 		"s.id",
 		"s.name",
@@ -911,11 +1029,21 @@ func (ds *Datastore) LoadHostSoftware(ctx context.Context, host *fleet.Host, inc
 		lookup[ip.SoftwareID] = append(lookup[ip.SoftwareID], ip.InstalledPath)
 	}
 
+	storedSources, err := listHostSoftwareInventorySourcesDB(ctx, ds.reader(ctx), host.ID)
+	if err != nil {
+		return err
+	}
+	sourcesLookup := make(map[uint][]hostSoftwareInventorySource)
+	for _, s := range storedSources {
+		sourcesLookup[s.SoftwareID] = append(sourcesLookup[s.SoftwareID], s)
+	}
+
 	host.Software = make([]fleet.HostSoftwareEntry, 0, len(software))
 	for _, s := range software {
 		host.Software = append(host.Software, fleet.HostSoftwareEntry{
-			Software:       s,
-			InstalledPaths: lookup[s.ID],
+			Software:         s,
+			InstalledPaths:   lookup[s.ID],
+			InventorySources: hostSoftwareInventorySources(s, sourcesLookup[s.ID]),
 		})
 	}
 	return nil
@@ -1091,6 +1219,39 @@ func (ds *Datastore) CountSoftware(ctx context.Context, opt fleet.SoftwareListOp
 	return countSoftwareDB(ctx, ds.reader(ctx), opt)
 }
 
+func (ds *Datastore) StreamSoftware(ctx context.Context, opt fleet.SoftwareListOptions, batchSize int, fn func([]fleet.Software) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+
+	var lastID uint
+	for {
+		opt.ListOptions = fleet.ListOptions{
+			OrderKey:   "id",
+			PerPage:    uint(batchSize),
+			MatchQuery: opt.ListOptions.MatchQuery,
+		}
+		if lastID > 0 {
+			opt.ListOptions.After = strconv.FormatUint(uint64(lastID), 10)
+		}
+
+		software, err := listSoftwareDB(ctx, ds.reader(ctx), opt)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "stream software")
+		}
+		if len(software) == 0 {
+			return nil
+		}
+		if err := fn(software); err != nil {
+			return err
+		}
+		if len(software) < batchSize {
+			return nil
+		}
+		lastID = software[len(software)-1].ID
+	}
+}
+
 // DeleteSoftwareVulnerabilities deletes the given list of software vulnerabilities
 func (ds *Datastore) DeleteSoftwareVulnerabilities(ctx context.Context, vulnerabilities []fleet.SoftwareVulnerability) error {
 	if len(vulnerabilities) == 0 {
@@ -0,0 +1,354 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// inventorySources are the sources that reported a software of a host, mapped
+// to the version they reported if it differs from the version of the
+// software (empty otherwise).
+type inventorySources map[fleet.SoftwareInventorySource]string
+
+// merge records that the source reported the software with the given version
+// (empty if it reported the version of the software). A source that reported
+// the version of the software keeps doing so.
+func (s inventorySources) merge(source fleet.SoftwareInventorySource, reportedVersion string) {
+	if v, ok := s[source]; ok && v == "" {
+		return
+	}
+	s[source] = reportedVersion
+}
+
+// top returns the source with the highest precedence.
+func (s inventorySources) top() fleet.SoftwareInventorySource {
+	var top fleet.SoftwareInventorySource
+	for src := range s {
+		if top == "" || src.Precedence() > top.Precedence() {
+			top = src
+		}
+	}
+	return top
+}
+
+// isDefault returns true if the software was only reported by its default
+// source (see defaultInventorySource), in which case the sources are not
+// stored.
+func (s inventorySources) isDefault(sw fleet.Software) bool {
+	v, ok := s[defaultInventorySource(sw)]
+	return len(s) == 1 && ok && v == ""
+}
+
+// defaultInventorySource returns the source of the software of a host that
+// has no stored sources, i.e. the software reported before the sources were
+// recorded and the software only reported by osquery (or by MDM for the MDM
+// software).
+func defaultInventorySource(sw fleet.Software) fleet.SoftwareInventorySource {
+	if sw.Source == fleet.SoftwareSourceMDM {
+		return fleet.SoftwareInventorySourceMDM
+	}
+	return fleet.SoftwareInventorySourceOsquery
+}
+
+// softwareProductKey identifies a software regardless of its version and of
+// the inventory source that reported it, so that the versions reported by
+// different sources can be compared. The bundle identifier of Apple apps is
+// used as they are reported with different sources by osquery and MDM.
+func softwareProductKey(sw fleet.Software) string {
+	if sw.BundleIdentifier != "" {
+		return "bundle" + fleet.SoftwareFieldSeparator + strings.ToLower(sw.BundleIdentifier)
+	}
+	return strings.Join([]string{strings.ToLower(sw.Name), sw.Source, sw.Browser, sw.ExtensionID}, fleet.SoftwareFieldSeparator)
+}
+
+// resolveHostSoftwareInventory returns the software installed on the host
+// after the source reported the incoming software, along with the sources of
+// each software (keyed by the unique string of the software).
+//
+// The current software and sources are the ones stored for the host, the
+// software without stored sources is attributed to its default source. The
+// precedence rules are:
+//   - the report of a full inventory source replaces the software it
+//     previously reported, and the software reported by the installer that is
+//     not in the report is no longer attributed to the installer, unless a
+//     source of higher precedence reported it;
+//   - the software installed on the host is the union of the software
+//     reported by the sources;
+//   - when sources report different versions of the same software (see
+//     softwareProductKey), only the versions of the source with the highest
+//     precedence are installed, the other sources are attributed to them with
+//     the version they reported.
+func resolveHostSoftwareInventory(
+	current []fleet.Software,
+	currentSources map[string]inventorySources,
+	source fleet.SoftwareInventorySource,
+	incoming []fleet.Software,
+) ([]fleet.Software, map[string]inventorySources) {
+	type entry struct {
+		software fleet.Software
+		sources  inventorySources
+	}
+	entries := make(map[string]*entry, len(current)+len(incoming))
+	add := func(sw fleet.Software, sources inventorySources) {
+		key := sw.ToUniqueStr()
+		e, ok := entries[key]
+		if !ok {
+			entries[key] = &entry{software: sw, sources: sources}
+			return
+		}
+		if sw.LastOpenedAt != nil {
+			e.software.LastOpenedAt = sw.LastOpenedAt
+		}
+		for src, v := range sources {
+			e.sources.merge(src, v)
+		}
+	}
+
+	incomingProducts := make(map[string][]fleet.Software)
+	var incomingKeys []string
+	for _, sw := range incoming {
+		key := softwareProductKey(sw)
+		if _, ok := incomingProducts[key]; !ok {
+			incomingKeys = append(incomingKeys, key)
+		}
+		incomingProducts[key] = append(incomingProducts[key], sw)
+	}
+
+	for _, sw := range current {
+		sources := make(inventorySources)
+		for src, v := range currentSources[sw.ToUniqueStr()] {
+			sources[src] = v
+		}
+		if len(sources) == 0 {
+			sources[defaultInventorySource(sw)] = ""
+		}
+		if source.IsFullInventory() {
+			delete(sources, source)
+			if _, ok := incomingProducts[softwareProductKey(sw)]; !ok && source.Precedence() >= sources.top().Precedence() {
+				delete(sources, fleet.SoftwareInventorySourceInstaller)
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+
+		// if no remaining source reported this version, the version reported
+		// by the remaining source of highest precedence is installed.
+		reported := false
+		for _, v := range sources {
+			if v == "" {
+				reported = true
+				break
+			}
+		}
+		if !reported {
+			sw.ID = 0
+			sw.Version = sources[sources.top()]
+			for src, v := range sources {
+				if v == sw.Version {
+					sources[src] = ""
+				}
+			}
+		}
+		add(sw, sources)
+	}
+
+	for _, key := range incomingKeys {
+		products := incomingProducts[key]
+
+		var existing []*entry
+		var ownerPrecedence int
+		for _, e := range entries {
+			if softwareProductKey(e.software) != key {
+				continue
+			}
+			existing = append(existing, e)
+			if p := e.sources.top().Precedence(); p > ownerPrecedence {
+				ownerPrecedence = p
+			}
+		}
+		sort.Slice(existing, func(i, j int) bool {
+			return existing[i].software.ToUniqueStr() < existing[j].software.ToUniqueStr()
+		})
+
+		if ownerPrecedence > source.Precedence() {
+			// a source of higher precedence reported this software, only record
+			// the version reported by this source.
+			for _, e := range existing {
+				reportedVersion := products[0].Version
+				for _, sw := range products {
+					if sw.Version == e.software.Version {
+						reportedVersion = ""
+						break
+					}
+				}
+				e.sources.merge(source, reportedVersion)
+			}
+			continue
+		}
+
+		// the versions reported by this source replace the versions reported by
+		// the sources of lower precedence, which are attributed to the reported
+		// versions.
+		reportedKeys := make(map[string]bool, len(products))
+		for _, sw := range products {
+			reportedKeys[sw.ToUniqueStr()] = true
+		}
+		var replaced []*entry
+		for _, e := range existing {
+			if !reportedKeys[e.software.ToUniqueStr()] {
+				replaced = append(replaced, e)
+				delete(entries, e.software.ToUniqueStr())
+			}
+		}
+		for _, sw := range products {
+			sources := inventorySources{source: ""}
+			for _, e := range replaced {
+				for src, v := range e.sources {
+					if src == source {
+						continue
+					}
+					if v == "" {
+						v = e.software.Version
+					}
+					if v == sw.Version {
+						v = ""
+					}
+					sources.merge(src, v)
+				}
+			}
+			add(sw, sources)
+		}
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resolved := make([]fleet.Software, 0, len(keys))
+	resolvedSources := make(map[string]inventorySources, len(keys))
+	for _, key := range keys {
+		resolved = append(resolved, entries[key].software)
+		resolvedSources[key] = entries[key].sources
+	}
+	return resolved, resolvedSources
+}
+
+type hostSoftwareInventorySource struct {
+	SoftwareID      uint                          `db:"software_id"`
+	Source          fleet.SoftwareInventorySource `db:"source"`
+	ReportedVersion string                        `db:"reported_version"`
+}
+
+func listHostSoftwareInventorySourcesDB(ctx context.Context, q sqlx.QueryerContext, hostID uint) ([]hostSoftwareInventorySource, error) {
+	const stmt = `
+		SELECT software_id, source, reported_version
+		FROM host_software_inventory_sources
+		WHERE host_id = ?`
+
+	var sources []hostSoftwareInventorySource
+	if err := sqlx.SelectContext(ctx, q, &sources, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host software inventory sources")
+	}
+	return sources, nil
+}
+
+// updateHostSoftwareInventorySourcesDB stores the sources of the software of
+// the host, the sources of the software only reported by its default source
+// are not stored. softwareIDs maps the unique string of the software to its
+// ID.
+func updateHostSoftwareInventorySourcesDB(
+	ctx context.Context,
+	tx sqlx.ExtContext,
+	hostID uint,
+	current []hostSoftwareInventorySource,
+	software []fleet.Software,
+	sources map[string]inventorySources,
+	softwareIDs map[string]uint,
+) error {
+	type sourceKey struct {
+		softwareID uint
+		source     fleet.SoftwareInventorySource
+	}
+	desired := make(map[sourceKey]string)
+	for _, sw := range software {
+		key := sw.ToUniqueStr()
+		id, ok := softwareIDs[key]
+		if !ok || sources[key].isDefault(sw) {
+			continue
+		}
+		for src, v := range sources[key] {
+			desired[sourceKey{id, src}] = v
+		}
+	}
+
+	var deleteArgs []interface{}
+	for _, s := range current {
+		k := sourceKey{s.SoftwareID, s.Source}
+		v, ok := desired[k]
+		if !ok {
+			deleteArgs = append(deleteArgs, s.SoftwareID, s.Source)
+			continue
+		}
+		if v == s.ReportedVersion {
+			delete(desired, k)
+		}
+	}
+
+	upsertArgs := make([]interface{}, 0, len(desired)*4)
+	for k, v := range desired {
+		upsertArgs = append(upsertArgs, hostID, k.softwareID, k.source, v)
+	}
+
+	const batchSize = 500
+	for len(deleteArgs) > 0 {
+		n := min(len(deleteArgs), batchSize*2)
+		stmt := fmt.Sprintf(`DELETE FROM host_software_inventory_sources WHERE host_id = ? AND (software_id, source) IN (%s)`,
+			strings.TrimSuffix(strings.Repeat("(?,?),", n/2), ","))
+		if _, err := tx.ExecContext(ctx, stmt, append([]interface{}{hostID}, deleteArgs[:n]...)...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete host software inventory sources")
+		}
+		deleteArgs = deleteArgs[n:]
+	}
+	for len(upsertArgs) > 0 {
+		n := min(len(upsertArgs), batchSize*4)
+		stmt := fmt.Sprintf(`
+			INSERT INTO host_software_inventory_sources (host_id, software_id, source, reported_version)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE reported_version = VALUES(reported_version)`,
+			strings.TrimSuffix(strings.Repeat("(?,?,?,?),", n/4), ","))
+		if _, err := tx.ExecContext(ctx, stmt, upsertArgs[:n]...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert host software inventory sources")
+		}
+		upsertArgs = upsertArgs[n:]
+	}
+	return nil
+}
+
+// hostSoftwareInventorySources returns the sources of the software of a host
+// in their API form, ordered by precedence.
+func hostSoftwareInventorySources(sw fleet.Software, stored []hostSoftwareInventorySource) []fleet.HostSoftwareInventorySource {
+	if len(stored) == 0 {
+		stored = []hostSoftwareInventorySource{{SoftwareID: sw.ID, Source: defaultInventorySource(sw)}}
+	}
+	sources := make([]fleet.HostSoftwareInventorySource, 0, len(stored))
+	for _, s := range stored {
+		version := s.ReportedVersion
+		if version == "" {
+			version = sw.Version
+		}
+		sources = append(sources, fleet.HostSoftwareInventorySource{Source: s.Source, Version: version})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Source.Precedence() > sources[j].Source.Precedence()
+	})
+	return sources
+}
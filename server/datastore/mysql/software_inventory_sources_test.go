@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveHostSoftwareInventory(t *testing.T) {
+	const (
+		osquery   = fleet.SoftwareInventorySourceOsquery
+		mdm       = fleet.SoftwareInventorySourceMDM
+		installer = fleet.SoftwareInventorySourceInstaller
+	)
+	chrome := func(version, source string) fleet.Software {
+		return fleet.Software{Name: "Google Chrome", Version: version, Source: source, BundleIdentifier: "com.google.Chrome"}
+	}
+	python := func(version string) fleet.Software {
+		return fleet.Software{Name: "python", Version: version, Source: "deb_packages"}
+	}
+	keys := func(sws ...fleet.Software) []string {
+		var res []string
+		for _, sw := range sws {
+			res = append(res, sw.ToUniqueStr())
+		}
+		return res
+	}
+	resolvedKeys := func(sws []fleet.Software) []string {
+		return keys(sws...)
+	}
+
+	t.Run("osquery only", func(t *testing.T) {
+		current := []fleet.Software{python("3.10"), chrome("123.0", "apps")}
+		resolved, sources := resolveHostSoftwareInventory(current, nil, osquery, []fleet.Software{python("3.10"), python("3.11")})
+		require.ElementsMatch(t, keys(python("3.10"), python("3.11")), resolvedKeys(resolved))
+		for _, sw := range resolved {
+			require.True(t, sources[sw.ToUniqueStr()].isDefault(sw))
+		}
+	})
+
+	t.Run("mdm software defaults to mdm", func(t *testing.T) {
+		current := []fleet.Software{chrome("123.0", fleet.SoftwareSourceMDM)}
+		resolved, sources := resolveHostSoftwareInventory(current, nil, installer, nil)
+		require.Equal(t, keys(chrome("123.0", fleet.SoftwareSourceMDM)), resolvedKeys(resolved))
+		require.Equal(t, inventorySources{mdm: ""}, sources[resolved[0].ToUniqueStr()])
+
+		// osquery replaces the version reported by MDM
+		resolved, sources = resolveHostSoftwareInventory(current, nil, osquery, []fleet.Software{chrome("124.0", "apps")})
+		require.Equal(t, keys(chrome("124.0", "apps")), resolvedKeys(resolved))
+		require.Equal(t, inventorySources{osquery: "", mdm: "123.0"}, sources[resolved[0].ToUniqueStr()])
+	})
+
+	t.Run("lower precedence is only recorded", func(t *testing.T) {
+		current := []fleet.Software{chrome("124.0", "apps")}
+		resolved, sources := resolveHostSoftwareInventory(current, nil, mdm, []fleet.Software{
+			chrome("123.0", fleet.SoftwareSourceMDM),
+			{Name: "Slack", Version: "4.38", Source: fleet.SoftwareSourceMDM, BundleIdentifier: "com.tinyspeck.slackmacgap"},
+		})
+		require.Len(t, resolved, 2)
+		require.Equal(t, inventorySources{osquery: "", mdm: "123.0"}, sources[chrome("124.0", "apps").ToUniqueStr()])
+
+		// the installer reports the same version as osquery
+		current = resolved
+		resolved, sources = resolveHostSoftwareInventory(current, sources, installer, []fleet.Software{chrome("124.0", "apps")})
+		require.Len(t, resolved, 2)
+		require.Equal(t, inventorySources{osquery: "", mdm: "123.0", installer: ""}, sources[chrome("124.0", "apps").ToUniqueStr()])
+	})
+
+	t.Run("installer upgrades its own software", func(t *testing.T) {
+		current := []fleet.Software{python("3.10")}
+		currentSources := map[string]inventorySources{python("3.10").ToUniqueStr(): {installer: ""}}
+		resolved, sources := resolveHostSoftwareInventory(current, currentSources, installer, []fleet.Software{python("3.11")})
+		require.Equal(t, keys(python("3.11")), resolvedKeys(resolved))
+		require.Equal(t, inventorySources{installer: ""}, sources[python("3.11").ToUniqueStr()])
+
+		// osquery does not report it, it is no longer installed
+		resolved, sources = resolveHostSoftwareInventory(resolved, sources, osquery, nil)
+		require.Empty(t, resolved)
+		require.Empty(t, sources)
+	})
+
+	t.Run("version of lower precedence is restored", func(t *testing.T) {
+		current := []fleet.Software{chrome("124.0", "apps")}
+		currentSources := map[string]inventorySources{chrome("124.0", "apps").ToUniqueStr(): {osquery: "", mdm: "123.0"}}
+
+		// osquery no longer reports Chrome, the version reported by MDM is
+		// installed
+		resolved, sources := resolveHostSoftwareInventory(current, currentSources, osquery, nil)
+		require.Equal(t, keys(chrome("123.0", "apps")), resolvedKeys(resolved))
+		require.Equal(t, inventorySources{mdm: ""}, sources[resolved[0].ToUniqueStr()])
+		require.Zero(t, resolved[0].ID)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/md5" // nolint:gosec (only used for tests)
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -38,6 +39,7 @@ func TestSoftware(t *testing.T) {
 		{"NothingChanged", testSoftwareNothingChanged},
 		{"LoadSupportsTonsOfCVEs", testSoftwareLoadSupportsTonsOfCVEs},
 		{"List", testSoftwareList},
+		{"Stream", testSoftwareStream},
 		{"SyncHostsSoftware", testSoftwareSyncHostsSoftware},
 		{"DeleteSoftwareVulnerabilities", testDeleteSoftwareVulnerabilities},
 		{"HostsByCVE", testHostsByCVE},
@@ -45,6 +47,8 @@ func TestSoftware(t *testing.T) {
 		{"UpdateHostSoftware", testUpdateHostSoftware},
 		{"UpdateHostSoftwareDeadlock", testUpdateHostSoftwareDeadlock},
 		{"UpdateHostSoftwareUpdatesSoftware", testUpdateHostSoftwareUpdatesSoftware},
+		{"UpdateHostSoftwareFromSource", testUpdateHostSoftwareFromSource},
+		{"DeleteHostSoftwareByBundleIdentifier", testDeleteHostSoftwareByBundleIdentifier},
 		{"ListSoftwareByHostIDShort", testListSoftwareByHostIDShort},
 		{"ListSoftwareVulnerabilitiesByHostIDsSource", testListSoftwareVulnerabilitiesByHostIDsSource},
 		{"InsertSoftwareVulnerability", testInsertSoftwareVulnerability},
@@ -506,6 +510,93 @@ func testSoftwareLoadSupportsTonsOfCVEs(t *testing.T, ds *Datastore) {
 	}
 }
 
+func testSoftwareStream(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	_, err := ds.UpdateHostSoftware(ctx, host1.ID, []fleet.Software{
+		{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+		{Name: "foo", Version: "0.0.2", Source: "chrome_extensions"},
+		{Name: "bar", Version: "0.0.1", Source: "deb_packages"},
+	})
+	require.NoError(t, err)
+	_, err = ds.UpdateHostSoftware(ctx, host2.ID, []fleet.Software{
+		{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+		{Name: "baz", Version: "0.0.1", Source: "deb_packages"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ds.SyncHostsSoftware(ctx, time.Now()))
+
+	require.NoError(t, ds.LoadHostSoftware(ctx, host1, false))
+	sort.Slice(host1.Software, func(i, j int) bool { return host1.Software[i].ID < host1.Software[j].ID })
+	_, err = ds.InsertSoftwareVulnerability(ctx, fleet.SoftwareVulnerability{
+		SoftwareID: host1.Software[0].ID, CVE: "CVE-2022-0001",
+	}, fleet.NVDSource)
+	require.NoError(t, err)
+	_, err = ds.InsertSoftwareVulnerability(ctx, fleet.SoftwareVulnerability{
+		SoftwareID: host1.Software[0].ID, CVE: "CVE-2022-0002",
+	}, fleet.NVDSource)
+	require.NoError(t, err)
+
+	all, _, err := ds.ListSoftware(ctx, fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{OrderKey: "id"}})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+
+	stream := func(opt fleet.SoftwareListOptions, batchSize int) ([][]uint, []fleet.Software) {
+		var (
+			batches  [][]uint
+			software []fleet.Software
+		)
+		err := ds.StreamSoftware(ctx, opt, batchSize, func(batch []fleet.Software) error {
+			ids := make([]uint, 0, len(batch))
+			for _, sw := range batch {
+				ids = append(ids, sw.ID)
+			}
+			batches = append(batches, ids)
+			software = append(software, batch...)
+			return nil
+		})
+		require.NoError(t, err)
+		return batches, software
+	}
+
+	// the vulnerabilities do not count in the size of the batches
+	batches, software := stream(fleet.SoftwareListOptions{WithHostCounts: true}, 3)
+	require.Equal(t, [][]uint{{all[0].ID, all[1].ID, all[2].ID}, {all[3].ID}}, batches)
+	require.Equal(t, all[0].ID, host1.Software[0].ID)
+	require.Len(t, software[0].Vulnerabilities, 2)
+	var fooCount int
+	for _, sw := range software {
+		if sw.Name == "foo" && sw.Version == "0.0.1" {
+			fooCount = sw.HostsCount
+		}
+	}
+	require.Equal(t, 2, fooCount)
+
+	// the pagination and ordering options are ignored, the query is applied
+	batches, _ = stream(fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{
+		MatchQuery: "foo",
+		Page:       1,
+		PerPage:    1,
+		OrderKey:   "name",
+	}}, 1)
+	require.Len(t, batches, 2)
+
+	batches, _ = stream(fleet.SoftwareListOptions{VulnerableOnly: true}, 0)
+	require.Equal(t, [][]uint{{host1.Software[0].ID}}, batches)
+
+	// streaming stops at the first error
+	var calls int
+	err = ds.StreamSoftware(ctx, fleet.SoftwareListOptions{}, 1, func(batch []fleet.Software) error {
+		calls++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, calls)
+}
+
 func testSoftwareList(t *testing.T, ds *Datastore) {
 	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
 	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
@@ -2929,3 +3020,158 @@ func testVerifySoftwareChecksum(t *testing.T, ds *Datastore) {
 		require.Equal(t, software[i], got)
 	}
 }
+
+func testUpdateHostSoftwareFromSource(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	loadSources := func() map[string][]fleet.HostSoftwareInventorySource {
+		require.NoError(t, ds.LoadHostSoftware(ctx, host, false))
+		sources := make(map[string][]fleet.HostSoftwareInventorySource, len(host.Software))
+		for _, s := range host.Software {
+			sources[s.Name+" "+s.Version] = s.InventorySources
+		}
+		return sources
+	}
+	countStored := func() int {
+		var count int
+		require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &count,
+			`SELECT COUNT(*) FROM host_software_inventory_sources WHERE host_id = ?`, host.ID))
+		return count
+	}
+
+	// software only reported by osquery is attributed to osquery without
+	// storing its sources
+	_, err := ds.UpdateHostSoftware(ctx, host.ID, []fleet.Software{
+		{Name: "Chrome", Version: "124.0", Source: "apps", BundleIdentifier: "com.google.Chrome"},
+		{Name: "zsh", Version: "5.9", Source: "homebrew_packages"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]fleet.HostSoftwareInventorySource{
+		"Chrome 124.0": {{Source: fleet.SoftwareInventorySourceOsquery, Version: "124.0"}},
+		"zsh 5.9":      {{Source: fleet.SoftwareInventorySourceOsquery, Version: "5.9"}},
+	}, loadSources())
+	require.Zero(t, countStored())
+
+	// MDM reports an older version of Chrome and another app, osquery has
+	// precedence over MDM
+	_, err = ds.UpdateHostSoftwareFromSource(ctx, host.ID, fleet.SoftwareInventorySourceMDM, []fleet.Software{
+		{Name: "Chrome", Version: "123.0", Source: fleet.SoftwareSourceMDM, BundleIdentifier: "com.google.Chrome"},
+		{Name: "Slack", Version: "4.38", Source: fleet.SoftwareSourceMDM, BundleIdentifier: "com.tinyspeck.slackmacgap"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]fleet.HostSoftwareInventorySource{
+		"Chrome 124.0": {
+			{Source: fleet.SoftwareInventorySourceOsquery, Version: "124.0"},
+			{Source: fleet.SoftwareInventorySourceMDM, Version: "123.0"},
+		},
+		"zsh 5.9":    {{Source: fleet.SoftwareInventorySourceOsquery, Version: "5.9"}},
+		"Slack 4.38": {{Source: fleet.SoftwareInventorySourceMDM, Version: "4.38"}},
+	}, loadSources())
+	require.Equal(t, 2, countStored())
+
+	// the installer reports a new version of Slack, MDM has precedence over the
+	// installer
+	_, err = ds.UpdateHostSoftwareFromSource(ctx, host.ID, fleet.SoftwareInventorySourceInstaller, []fleet.Software{
+		{Name: "Slack", Version: "4.39", Source: "apps", BundleIdentifier: "com.tinyspeck.slackmacgap"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.HostSoftwareInventorySource{
+		{Source: fleet.SoftwareInventorySourceMDM, Version: "4.38"},
+		{Source: fleet.SoftwareInventorySourceInstaller, Version: "4.39"},
+	}, loadSources()["Slack 4.38"])
+
+	// osquery reports the new version of Slack, which replaces the one reported
+	// by MDM, and no longer reports zsh
+	_, err = ds.UpdateHostSoftware(ctx, host.ID, []fleet.Software{
+		{Name: "Chrome", Version: "124.0", Source: "apps", BundleIdentifier: "com.google.Chrome"},
+		{Name: "Slack", Version: "4.39", Source: "apps", BundleIdentifier: "com.tinyspeck.slackmacgap"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]fleet.HostSoftwareInventorySource{
+		"Chrome 124.0": {
+			{Source: fleet.SoftwareInventorySourceOsquery, Version: "124.0"},
+			{Source: fleet.SoftwareInventorySourceMDM, Version: "123.0"},
+		},
+		"Slack 4.39": {
+			{Source: fleet.SoftwareInventorySourceOsquery, Version: "4.39"},
+			{Source: fleet.SoftwareInventorySourceMDM, Version: "4.38"},
+			{Source: fleet.SoftwareInventorySourceInstaller, Version: "4.39"},
+		},
+	}, loadSources())
+
+	// the software of the host is no longer attributed to MDM after it reports
+	// an empty list
+	_, err = ds.UpdateHostSoftwareFromSource(ctx, host.ID, fleet.SoftwareInventorySourceMDM, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]fleet.HostSoftwareInventorySource{
+		"Chrome 124.0": {{Source: fleet.SoftwareInventorySourceOsquery, Version: "124.0"}},
+		"Slack 4.39": {
+			{Source: fleet.SoftwareInventorySourceOsquery, Version: "4.39"},
+			{Source: fleet.SoftwareInventorySourceInstaller, Version: "4.39"},
+		},
+	}, loadSources())
+
+	// the sources are deleted with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	require.Zero(t, countStored())
+}
+
+func testDeleteHostSoftwareByBundleIdentifier(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	listNames := func(host *fleet.Host) []string {
+		require.NoError(t, ds.LoadHostSoftware(ctx, host, false))
+		var names []string
+		for _, s := range host.Software {
+			names = append(names, s.Name+" "+s.Version)
+		}
+		sort.Strings(names)
+		return names
+	}
+	countSoftware := func(bundleID string) int {
+		var count int
+		require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &count,
+			`SELECT COUNT(*) FROM software WHERE bundle_identifier = ?`, bundleID))
+		return count
+	}
+
+	// Slack is reported by both osquery and MDM on host1, with different
+	// versions, and by osquery on host2. Numbers is only on host1.
+	_, err := ds.UpdateHostSoftware(ctx, host1.ID, []fleet.Software{
+		{Name: "Slack", Version: "4.39", Source: "apps", BundleIdentifier: "com.tinyspeck.slackmacgap"},
+		{Name: "zsh", Version: "5.9", Source: "homebrew_packages"},
+	})
+	require.NoError(t, err)
+	_, err = ds.UpdateHostSoftwareFromSource(ctx, host1.ID, fleet.SoftwareInventorySourceMDM, []fleet.Software{
+		{Name: "Slack", Version: "4.38", Source: fleet.SoftwareSourceMDM, BundleIdentifier: "com.tinyspeck.slackmacgap"},
+		{Name: "Numbers", Version: "14.0", Source: fleet.SoftwareSourceMDM, BundleIdentifier: "com.apple.iWork.Numbers"},
+	})
+	require.NoError(t, err)
+	_, err = ds.UpdateHostSoftware(ctx, host2.ID, []fleet.Software{
+		{Name: "Slack", Version: "4.39", Source: "apps", BundleIdentifier: "com.tinyspeck.slackmacgap"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Numbers 14.0", "Slack 4.39", "zsh 5.9"}, listNames(host1))
+
+	// unknown bundle identifier
+	require.NoError(t, ds.DeleteHostSoftwareByBundleIdentifier(ctx, host1.ID, "com.example.unknown"))
+	require.Equal(t, []string{"Numbers 14.0", "Slack 4.39", "zsh 5.9"}, listNames(host1))
+
+	// Slack is removed from host1 with its sources, but kept on host2
+	require.NoError(t, ds.DeleteHostSoftwareByBundleIdentifier(ctx, host1.ID, "com.tinyspeck.slackmacgap"))
+	require.Equal(t, []string{"Numbers 14.0", "zsh 5.9"}, listNames(host1))
+	require.Equal(t, []string{"Slack 4.39"}, listNames(host2))
+	require.Equal(t, 1, countSoftware("com.tinyspeck.slackmacgap"))
+	var count int
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &count,
+		`SELECT COUNT(*) FROM host_software_inventory_sources WHERE host_id = ?`, host1.ID))
+	require.Zero(t, count)
+
+	// Numbers is no longer installed on any host
+	require.NoError(t, ds.DeleteHostSoftwareByBundleIdentifier(ctx, host1.ID, "com.apple.iWork.Numbers"))
+	require.Equal(t, []string{"zsh 5.9"}, listNames(host1))
+	require.Zero(t, countSoftware("com.apple.iWork.Numbers"))
+}
@@ -23,86 +23,6 @@ type statistics struct {
 func (ds *Datastore) ShouldSendStatistics(ctx context.Context, frequency time.Duration, config config.FleetConfig) (fleet.StatisticsPayload, bool, error) {
 	lic, _ := license.FromContext(ctx)
 
-	computeStats := func(stats *fleet.StatisticsPayload, since time.Time) error {
-		enrolledHostsByOS, amountEnrolledHosts, err := amountEnrolledHostsByOSDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount enrolled hosts by os")
-		}
-		amountUsers, err := amountUsersDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount users")
-		}
-		amountTeams, err := amountTeamsDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount teams")
-		}
-		amountPolicies, err := amountPoliciesDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount policies")
-		}
-		amountLabels, err := amountLabelsDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount labels")
-		}
-		appConfig, err := ds.AppConfig(ctx)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "statistics app config")
-		}
-		amountWeeklyUsers, err := amountActiveUsersSinceDB(ctx, ds.writer(ctx), since)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount active users")
-		}
-		amountPolicyViolationDaysActual, amountPolicyViolationDaysPossible, err := amountPolicyViolationDaysDB(ctx, ds.writer(ctx))
-		if err == sql.ErrNoRows {
-			level.Debug(ds.logger).Log("msg", "amount policy violation days", "err", err) //nolint:errcheck
-		} else if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount policy violation days")
-		}
-		storedErrs, err := ctxerr.Aggregate(ctx)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "statistics error store")
-		}
-		amountHostsNotResponding, err := countHostsNotRespondingDB(ctx, ds.writer(ctx), ds.logger, config)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount hosts not responding")
-		}
-		amountHostsByOrbitVersion, err := amountHostsByOrbitVersionDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount hosts by orbit version")
-		}
-		amountHostsByOsqueryVersion, err := amountHostsByOsqueryVersionDB(ctx, ds.writer(ctx))
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "amount hosts by osquery version")
-		}
-
-		stats.NumHostsEnrolled = amountEnrolledHosts
-		stats.NumUsers = amountUsers
-		stats.NumTeams = amountTeams
-		stats.NumPolicies = amountPolicies
-		stats.NumLabels = amountLabels
-		stats.SoftwareInventoryEnabled = appConfig.Features.EnableSoftwareInventory
-		stats.VulnDetectionEnabled = config.Vulnerabilities.DatabasesPath != "" || appConfig.VulnerabilitySettings.DatabasesPath != ""
-		stats.SystemUsersEnabled = appConfig.Features.EnableHostUsers
-		stats.HostsStatusWebHookEnabled = appConfig.WebhookSettings.HostStatusWebhook.Enable
-		stats.MDMMacOsEnabled = appConfig.MDM.EnabledAndConfigured
-		stats.HostExpiryEnabled = appConfig.HostExpirySettings.HostExpiryEnabled
-		stats.MDMWindowsEnabled = appConfig.MDM.WindowsEnabledAndConfigured
-		stats.LiveQueryDisabled = appConfig.ServerSettings.LiveQueryDisabled
-		stats.NumWeeklyActiveUsers = amountWeeklyUsers
-		stats.NumWeeklyPolicyViolationDaysActual = amountPolicyViolationDaysActual
-		stats.NumWeeklyPolicyViolationDaysPossible = amountPolicyViolationDaysPossible
-		stats.HostsEnrolledByOperatingSystem = enrolledHostsByOS
-		stats.HostsEnrolledByOrbitVersion = amountHostsByOrbitVersion
-		stats.HostsEnrolledByOsqueryVersion = amountHostsByOsqueryVersion
-		stats.StoredErrors = storedErrs
-		stats.NumHostsNotResponding = amountHostsNotResponding
-		stats.Organization = "unknown"
-		if lic != nil && lic.IsPremium() {
-			stats.Organization = lic.Organization
-		}
-		return nil
-	}
-
 	dest := statistics{}
 	err := sqlx.GetContext(ctx, ds.writer(ctx), &dest, `SELECT created_at, updated_at, anonymous_identifier FROM statistics LIMIT 1`)
 	if err != nil {
@@ -125,9 +45,12 @@ func (ds *Datastore) ShouldSendStatistics(ctx context.Context, frequency time.Du
 			if lic != nil {
 				stats.LicenseTier = lic.Tier
 			}
-			if err := computeStats(&stats, time.Now().Add(-frequency)); err != nil {
+			if err := ds.computeStatistics(ctx, &stats, config, time.Now().Add(-frequency)); err != nil {
 				return fleet.StatisticsPayload{}, false, ctxerr.Wrap(ctx, err, "compute statistics")
 			}
+			if stats.StoredErrors, err = ctxerr.Aggregate(ctx); err != nil {
+				return fleet.StatisticsPayload{}, false, ctxerr.Wrap(ctx, err, "statistics error store")
+			}
 
 			return stats, true, nil
 		}
@@ -150,13 +73,95 @@ func (ds *Datastore) ShouldSendStatistics(ctx context.Context, frequency time.Du
 	if lic != nil {
 		stats.LicenseTier = lic.Tier
 	}
-	if err := computeStats(&stats, lastUpdated); err != nil {
+	if err := ds.computeStatistics(ctx, &stats, config, lastUpdated); err != nil {
 		return fleet.StatisticsPayload{}, false, ctxerr.Wrap(ctx, err, "compute statistics")
 	}
+	if stats.StoredErrors, err = ctxerr.Aggregate(ctx); err != nil {
+		return fleet.StatisticsPayload{}, false, ctxerr.Wrap(ctx, err, "statistics error store")
+	}
 
 	return stats, true, nil
 }
 
+// computeStatistics computes the usage statistics in stats, the weekly active
+// users are the ones active since since. The stored errors are not included.
+func (ds *Datastore) computeStatistics(ctx context.Context, stats *fleet.StatisticsPayload, config config.FleetConfig, since time.Time) error {
+	lic, _ := license.FromContext(ctx)
+
+	enrolledHostsByOS, amountEnrolledHosts, err := amountEnrolledHostsByOSDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount enrolled hosts by os")
+	}
+	amountUsers, err := amountUsersDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount users")
+	}
+	amountTeams, err := amountTeamsDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount teams")
+	}
+	amountPolicies, err := amountPoliciesDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount policies")
+	}
+	amountLabels, err := amountLabelsDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount labels")
+	}
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "statistics app config")
+	}
+	amountWeeklyUsers, err := amountActiveUsersSinceDB(ctx, ds.writer(ctx), since)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount active users")
+	}
+	amountPolicyViolationDaysActual, amountPolicyViolationDaysPossible, err := amountPolicyViolationDaysDB(ctx, ds.writer(ctx))
+	if err == sql.ErrNoRows {
+		level.Debug(ds.logger).Log("msg", "amount policy violation days", "err", err) //nolint:errcheck
+	} else if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount policy violation days")
+	}
+	amountHostsNotResponding, err := countHostsNotRespondingDB(ctx, ds.writer(ctx), ds.logger, config)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount hosts not responding")
+	}
+	amountHostsByOrbitVersion, err := amountHostsByOrbitVersionDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount hosts by orbit version")
+	}
+	amountHostsByOsqueryVersion, err := amountHostsByOsqueryVersionDB(ctx, ds.writer(ctx))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "amount hosts by osquery version")
+	}
+
+	stats.NumHostsEnrolled = amountEnrolledHosts
+	stats.NumUsers = amountUsers
+	stats.NumTeams = amountTeams
+	stats.NumPolicies = amountPolicies
+	stats.NumLabels = amountLabels
+	stats.SoftwareInventoryEnabled = appConfig.Features.EnableSoftwareInventory
+	stats.VulnDetectionEnabled = config.Vulnerabilities.DatabasesPath != "" || appConfig.VulnerabilitySettings.DatabasesPath != ""
+	stats.SystemUsersEnabled = appConfig.Features.EnableHostUsers
+	stats.HostsStatusWebHookEnabled = appConfig.WebhookSettings.HostStatusWebhook.Enable
+	stats.MDMMacOsEnabled = appConfig.MDM.EnabledAndConfigured
+	stats.HostExpiryEnabled = appConfig.HostExpirySettings.HostExpiryEnabled
+	stats.MDMWindowsEnabled = appConfig.MDM.WindowsEnabledAndConfigured
+	stats.LiveQueryDisabled = appConfig.ServerSettings.LiveQueryDisabled
+	stats.NumWeeklyActiveUsers = amountWeeklyUsers
+	stats.NumWeeklyPolicyViolationDaysActual = amountPolicyViolationDaysActual
+	stats.NumWeeklyPolicyViolationDaysPossible = amountPolicyViolationDaysPossible
+	stats.HostsEnrolledByOperatingSystem = enrolledHostsByOS
+	stats.HostsEnrolledByOrbitVersion = amountHostsByOrbitVersion
+	stats.HostsEnrolledByOsqueryVersion = amountHostsByOsqueryVersion
+	stats.NumHostsNotResponding = amountHostsNotResponding
+	stats.Organization = "unknown"
+	if lic != nil && lic.IsPremium() {
+		stats.Organization = lic.Organization
+	}
+	return nil
+}
+
 func (ds *Datastore) RecordStatisticsSent(ctx context.Context) error {
 	_, err := ds.writer(ctx).ExecContext(ctx, `UPDATE statistics SET updated_at = CURRENT_TIMESTAMP LIMIT 1`)
 	return ctxerr.Wrap(ctx, err, "update statistics")
@@ -106,7 +106,7 @@ func testStatisticsShouldSend(t *testing.T, ds *Datastore) {
 	// Create host_orbit_info record for test
 	require.NoError(
 		t, ds.SetOrUpdateHostOrbitInfo(
-			ctx, h1.ID, "1.1.0", sql.NullString{String: "1.1.0", Valid: true}, sql.NullBool{Bool: true, Valid: true},
+			ctx, h1.ID, "1.1.0", sql.NullString{String: "1.1.0", Valid: true}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{},
 		),
 	)
 
@@ -128,7 +128,7 @@ func testStatisticsShouldSend(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 	// Create a session for user baz, but not qux (so only 1 is active)
-	_, err = ds.NewSession(ctx, u1.ID, "session_key")
+	_, err = ds.NewSession(ctx, u1.ID, "session_key", "", "")
 	require.NoError(t, err)
 
 	// Create new team for test
@@ -316,11 +316,11 @@ func testStatisticsShouldSend(t *testing.T, ds *Datastore) {
 	assert.Equal(t, `[{"count":10,"loc":["a","b","c"]}]`, string(stats.StoredErrors))
 
 	// Create multiple new sessions for a single user
-	_, err = ds.NewSession(ctx, u1.ID, "session_key2")
+	_, err = ds.NewSession(ctx, u1.ID, "session_key2", "", "")
 	require.NoError(t, err)
-	_, err = ds.NewSession(ctx, u1.ID, "session_key3")
+	_, err = ds.NewSession(ctx, u1.ID, "session_key3", "", "")
 	require.NoError(t, err)
-	_, err = ds.NewSession(ctx, u1.ID, "session_key4")
+	_, err = ds.NewSession(ctx, u1.ID, "session_key4", "", "")
 	require.NoError(t, err)
 
 	// CleanupStatistics resets policy violation days
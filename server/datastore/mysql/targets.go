@@ -15,7 +15,7 @@ func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamF
 	// host.Status and GenerateHostStatusStatistics - that is, the intervals associated
 	// with each status must be the same.
 
-	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 {
+	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 && len(targets.HostGroupIDs) == 0 {
 		// No need to query if no targets selected
 		return fleet.TargetMetrics{}, nil
 	}
@@ -26,14 +26,14 @@ func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamF
 	sql := fmt.Sprintf(`
 		SELECT
 			COUNT(*) total,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) <= ? THEN 1 ELSE 0 END), 0) offline,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) > ? THEN 1 ELSE 0 END), 0) online,
-			COALESCE(SUM(CASE WHEN DATE_ADD(created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new
+			COALESCE(SUM(CASE WHEN DATE_ADD(%s, INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
+			COALESCE(SUM(CASE WHEN %s <= ? THEN 1 ELSE 0 END), 0) offline,
+			COALESCE(SUM(CASE WHEN %s > ? THEN 1 ELSE 0 END), 0) online,
+			COALESCE(SUM(CASE WHEN DATE_ADD(h.created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new
 		FROM hosts h
 		LEFT JOIN host_seen_times hst ON (h.id=hst.host_id)
 		WHERE %s AND %s`,
-		fleet.OnlineIntervalBuffer, fleet.OnlineIntervalBuffer,
+		hostLastSeenSQL, hostOnlineUntilSQL, hostOnlineUntilSQL,
 		queryTargetLogicCondition, ds.whereFilterHostsByTeams(filter, "h"),
 	)
 
@@ -58,6 +58,9 @@ func targetSQLCondAndArgs(targets fleet.HostTargets) (sql string, args []interfa
 	/* The host was selected explicitly. */
 	id IN (? /* queryHostIDs */)
 	OR
+	/* The host is a member of a selected host group. */
+	id IN (SELECT host_id FROM host_group_members WHERE host_group_id IN (? /* queryHostGroupIDs */))
+	OR
 	(
 		/* 'All hosts' builtin label was selected. */
 		id IN (SELECT DISTINCT host_id FROM label_membership WHERE label_id = 6 AND label_id IN (? /* queryLabelIDs */))
@@ -101,6 +104,10 @@ func targetSQLCondAndArgs(targets fleet.HostTargets) (sql string, args []interfa
 	for _, id := range targets.HostIDs {
 		queryHostIDs = append(queryHostIDs, int(id))
 	}
+	queryHostGroupIDs := []int{-1}
+	for _, id := range targets.HostGroupIDs {
+		queryHostGroupIDs = append(queryHostGroupIDs, int(id))
+	}
 	queryTeamIDs := []int{-1}
 	extraTeamIDCondition := ""
 	for _, id := range targets.TeamIDs {
@@ -116,6 +123,7 @@ func targetSQLCondAndArgs(targets fleet.HostTargets) (sql string, args []interfa
 
 	return fmt.Sprintf(queryTargetLogicCondition, extraTeamIDCondition), []interface{}{
 		queryHostIDs,
+		queryHostGroupIDs,
 		queryLabelIDs,
 		labelsSpecified, teamsSpecified,
 		queryLabelIDs, queryLabelIDs,
@@ -125,7 +133,7 @@ func targetSQLCondAndArgs(targets fleet.HostTargets) (sql string, args []interfa
 }
 
 func (ds *Datastore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
-	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 {
+	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 && len(targets.HostGroupIDs) == 0 {
 		// No need to query if no targets selected
 		return []uint{}, nil
 	}
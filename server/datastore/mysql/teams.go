@@ -17,7 +17,7 @@ import (
 
 var teamSearchColumns = []string{"name"}
 
-const teamColumns = `id, created_at, name, description, config`
+const teamColumns = `id, created_at, name, description, config, tenant_id`
 
 func (ds *Datastore) NewTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 	// We must normalize the name for full Unicode support (Unicode equivalence).
@@ -336,7 +336,7 @@ func (ds *Datastore) SearchTeams(ctx context.Context, filter fleet.TeamFilter, m
 
 func (ds *Datastore) TeamEnrollSecrets(ctx context.Context, teamID uint) ([]*fleet.EnrollSecret, error) {
 	sql := `
-		SELECT secret, team_id, created_at FROM enroll_secrets
+		SELECT secret, team_id, created_at, labels, custom_fields FROM enroll_secrets
 		WHERE team_id = ?
 	`
 	var secrets []*fleet.EnrollSecret
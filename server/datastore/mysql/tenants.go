@@ -0,0 +1,306 @@
+package mysql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// tenantEncryptionKeySize is the size of the AES-256 keys that encrypt the
+// tenants' escrowed secrets.
+const tenantEncryptionKeySize = 32
+
+const tenantSelectColumns = `
+	t.id,
+	t.name,
+	t.description,
+	t.config,
+	t.created_at,
+	t.updated_at,
+	(SELECT COUNT(*) FROM teams tm WHERE tm.tenant_id = t.id) AS team_count,
+	(SELECT COUNT(*) FROM users u WHERE u.tenant_id = t.id) AS user_count
+`
+
+// newTenantEncryptionKey generates a new tenant encryption key and returns it
+// in clear and encrypted with the server private key.
+func (ds *Datastore) newTenantEncryptionKey() (key, encrypted []byte, err error) {
+	key = make([]byte, tenantEncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("generate tenant encryption key: %w", err)
+	}
+	encrypted, err = encrypt(key, ds.serverPrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypt tenant encryption key: %w", err)
+	}
+	return key, encrypted, nil
+}
+
+func (ds *Datastore) NewTenant(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
+	_, encryptedKey, err := ds.newTenantEncryptionKey()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new tenant")
+	}
+
+	const stmt = `INSERT INTO tenants (name, description, config, encryption_key) VALUES (?, ?, ?, ?)`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, tenant.Name, tenant.Description, tenant.Config, encryptedKey)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("Tenant", tenant.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "inserting tenant")
+	}
+
+	id, _ := res.LastInsertId() // cannot fail with the mysql driver
+	return tenantDB(ctx, ds.writer(ctx), uint(id))
+}
+
+func (ds *Datastore) Tenant(ctx context.Context, id uint) (*fleet.Tenant, error) {
+	return tenantDB(ctx, ds.reader(ctx), id)
+}
+
+func tenantDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.Tenant, error) {
+	stmt := `SELECT ` + tenantSelectColumns + ` FROM tenants t WHERE t.id = ?`
+
+	var tenant fleet.Tenant
+	if err := sqlx.GetContext(ctx, q, &tenant, stmt, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("Tenant").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting tenant")
+	}
+	return &tenant, nil
+}
+
+func (ds *Datastore) ListTenants(ctx context.Context) ([]*fleet.Tenant, error) {
+	stmt := `SELECT ` + tenantSelectColumns + ` FROM tenants t ORDER BY t.name`
+
+	tenants := []*fleet.Tenant{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &tenants, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing tenants")
+	}
+	return tenants, nil
+}
+
+func (ds *Datastore) SaveTenant(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
+	const stmt = `UPDATE tenants SET name = ?, description = ?, config = ? WHERE id = ?`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, tenant.Name, tenant.Description, tenant.Config, tenant.ID); err != nil {
+		if isDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("Tenant", tenant.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "updating tenant")
+	}
+	return tenantDB(ctx, ds.writer(ctx), tenant.ID)
+}
+
+func (ds *Datastore) DeleteTenant(ctx context.Context, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM tenants WHERE id = ?`, id)
+	if err != nil {
+		if isMySQLForeignKey(err) {
+			return ctxerr.Wrap(ctx, foreignKey("tenants", fmt.Sprint(id)), "tenant still has teams or users")
+		}
+		return ctxerr.Wrap(ctx, err, "deleting tenant")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("Tenant").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) SetTeamsTenant(ctx context.Context, tenantID *uint, teamIDs []uint) error {
+	return ds.setTenantDB(ctx, "teams", tenantID, teamIDs)
+}
+
+func (ds *Datastore) SetUsersTenant(ctx context.Context, tenantID *uint, userIDs []uint) error {
+	return ds.setTenantDB(ctx, "users", tenantID, userIDs)
+}
+
+func (ds *Datastore) setTenantDB(ctx context.Context, table string, tenantID *uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, args, err := sqlx.In(fmt.Sprintf(`UPDATE %s SET tenant_id = ? WHERE id IN (?)`, table), tenantID, ids)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "build set tenant of %s", table)
+	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		if isChildForeignKeyError(err) {
+			return ctxerr.Wrap(ctx, notFound("Tenant").WithID(*tenantID))
+		}
+		return ctxerr.Wrapf(ctx, err, "set tenant of %s", table)
+	}
+	return nil
+}
+
+func (ds *Datastore) TenantAppConfig(ctx context.Context, tenantID uint) (*fleet.AppConfig, error) {
+	tenant, err := ds.Tenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	appCfg, err := ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	return tenant.Config.ApplyTo(appCfg), nil
+}
+
+// tenantEncryptionKeyDB returns the tenant's encryption key in clear. If
+// forUpdate is true, the tenant's row is locked until the end of the
+// transaction so that its key is not rotated concurrently.
+func (ds *Datastore) tenantEncryptionKeyDB(ctx context.Context, q sqlx.QueryerContext, tenantID uint, forUpdate bool) ([]byte, error) {
+	return tenantEncryptionKeyDB(ctx, q, ds.serverPrivateKey, tenantID, forUpdate)
+}
+
+func tenantEncryptionKeyDB(ctx context.Context, q sqlx.QueryerContext, serverPrivateKey string, tenantID uint, forUpdate bool) ([]byte, error) {
+	stmt := `SELECT encryption_key FROM tenants WHERE id = ?`
+	if forUpdate {
+		stmt += ` FOR UPDATE`
+	}
+	var encrypted []byte
+	if err := sqlx.GetContext(ctx, q, &encrypted, stmt, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("Tenant").WithID(tenantID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting tenant encryption key")
+	}
+	key, err := decrypt(encrypted, serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decrypting tenant encryption key")
+	}
+	return key, nil
+}
+
+func (ds *Datastore) SetTenantMDMAssets(ctx context.Context, tenantID uint, assets []fleet.TenantMDMAsset) error {
+	if len(assets) == 0 {
+		return nil
+	}
+
+	const stmt = `
+		INSERT INTO tenant_mdm_assets (tenant_id, name, value)
+		VALUES %s
+		ON DUPLICATE KEY UPDATE value = VALUES(value)
+	`
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		key, err := ds.tenantEncryptionKeyDB(ctx, tx, tenantID, true)
+		if err != nil {
+			return err
+		}
+		aesGCM, err := newAESGCM(key)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "tenant cipher")
+		}
+
+		args := make([]any, 0, 3*len(assets))
+		for _, asset := range assets {
+			encrypted, err := sealAESGCM(aesGCM, asset.Value)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "encrypting tenant MDM asset %s", asset.Name)
+			}
+			args = append(args, tenantID, asset.Name, encrypted)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(assets)), ",")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(stmt, values), args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upserting tenant MDM assets")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) GetTenantMDMAssets(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error) {
+	return getTenantMDMAssetsDB(ctx, ds.reader(ctx), ds.serverPrivateKey, tenantID, names)
+}
+
+func getTenantMDMAssetsDB(
+	ctx context.Context,
+	q sqlx.QueryerContext,
+	serverPrivateKey string,
+	tenantID uint,
+	names []fleet.TenantMDMAssetName,
+) (map[fleet.TenantMDMAssetName][]byte, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	key, err := tenantEncryptionKeyDB(ctx, q, serverPrivateKey, tenantID, false)
+	if err != nil {
+		return nil, err
+	}
+	aesGCM, err := newAESGCM(key)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "tenant cipher")
+	}
+
+	stmt, args, err := sqlx.In(`SELECT name, value FROM tenant_mdm_assets WHERE tenant_id = ? AND name IN (?)`, tenantID, names)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build select tenant MDM assets")
+	}
+	var rows []fleet.TenantMDMAsset
+	if err := sqlx.SelectContext(ctx, q, &rows, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting tenant MDM assets")
+	}
+
+	assets := make(map[fleet.TenantMDMAssetName][]byte, len(rows))
+	for _, row := range rows {
+		decrypted, err := openAESGCM(aesGCM, row.Value)
+		if err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "decrypting tenant MDM asset %s", row.Name)
+		}
+		assets[row.Name] = decrypted
+	}
+	return assets, nil
+}
+
+func (ds *Datastore) RotateTenantEncryptionKey(ctx context.Context, tenantID uint) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// lock the tenant so that its assets are not updated with the old key
+		// while they are re-encrypted.
+		oldKey, err := ds.tenantEncryptionKeyDB(ctx, tx, tenantID, true)
+		if err != nil {
+			return err
+		}
+		oldGCM, err := newAESGCM(oldKey)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "old tenant cipher")
+		}
+
+		newKey, newEncrypted, err := ds.newTenantEncryptionKey()
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "rotate tenant encryption key")
+		}
+		newGCM, err := newAESGCM(newKey)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "new tenant cipher")
+		}
+
+		var assets []fleet.TenantMDMAsset
+		if err := sqlx.SelectContext(ctx, tx, &assets, `SELECT name, value FROM tenant_mdm_assets WHERE tenant_id = ?`, tenantID); err != nil {
+			return ctxerr.Wrap(ctx, err, "selecting tenant MDM assets")
+		}
+		for _, asset := range assets {
+			decrypted, err := openAESGCM(oldGCM, asset.Value)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "decrypting tenant MDM asset %s", asset.Name)
+			}
+			reencrypted, err := sealAESGCM(newGCM, decrypted)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "encrypting tenant MDM asset %s", asset.Name)
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE tenant_mdm_assets SET value = ? WHERE tenant_id = ? AND name = ?`,
+				reencrypted, tenantID, asset.Name); err != nil {
+				return ctxerr.Wrapf(ctx, err, "updating tenant MDM asset %s", asset.Name)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE tenants SET encryption_key = ? WHERE id = ?`, newEncrypted, tenantID); err != nil {
+			return ctxerr.Wrap(ctx, err, "updating tenant encryption key")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,473 @@
+package mysql
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/cryptoutil"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testTenantsCRUD},
+		{"Isolation", testTenantsIsolation},
+		{"AppConfig", testTenantAppConfig},
+		{"HostExpiry", testTenantHostExpiry},
+		{"MDMAssets", testTenantMDMAssets},
+		{"PushCert", testTenantPushCert},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testTenantsCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tenants, err := ds.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Empty(t, tenants)
+
+	t1, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1", Description: "desc"})
+	require.NoError(t, err)
+	require.NotZero(t, t1.ID)
+	require.Equal(t, "desc", t1.Description)
+
+	_, err = ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1"})
+	var existsErr fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &existsErr)
+
+	t2, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "a-tenant2"})
+	require.NoError(t, err)
+
+	tenants, err = ds.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	require.Equal(t, t2.ID, tenants[0].ID)
+	require.Equal(t, t1.ID, tenants[1].ID)
+
+	t1.Name = "tenant1-renamed"
+	t1.Config.OrgInfo = &fleet.OrgInfo{OrgName: "Customer"}
+	t1, err = ds.SaveTenant(ctx, t1)
+	require.NoError(t, err)
+	require.Equal(t, "tenant1-renamed", t1.Name)
+	require.Equal(t, "Customer", t1.Config.OrgInfo.OrgName)
+
+	t2.Name = t1.Name
+	_, err = ds.SaveTenant(ctx, t2)
+	require.ErrorAs(t, err, &existsErr)
+
+	var nfe fleet.NotFoundError
+	_, err = ds.SaveTenant(ctx, &fleet.Tenant{ID: 999, Name: "nope"})
+	require.ErrorAs(t, err, &nfe)
+
+	// a tenant with teams or users cannot be deleted
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	user := test.NewUser(t, ds, "user1", "user1@example.com", true)
+	require.NoError(t, ds.SetTeamsTenant(ctx, &t1.ID, []uint{team.ID}))
+	require.NoError(t, ds.SetUsersTenant(ctx, &t1.ID, []uint{user.ID}))
+
+	t1, err = ds.Tenant(ctx, t1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, t1.TeamCount)
+	require.Equal(t, 1, t1.UserCount)
+
+	user, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, t1.ID, *user.TenantID)
+
+	err = ds.DeleteTenant(ctx, t1.ID)
+	var fkErr fleet.ForeignKeyError
+	require.ErrorAs(t, err, &fkErr)
+
+	require.NoError(t, ds.SetTeamsTenant(ctx, nil, []uint{team.ID}))
+	require.NoError(t, ds.SetUsersTenant(ctx, nil, []uint{user.ID}))
+	require.NoError(t, ds.DeleteTenant(ctx, t1.ID))
+
+	_, err = ds.Tenant(ctx, t1.ID)
+	require.ErrorAs(t, err, &nfe)
+	err = ds.DeleteTenant(ctx, t1.ID)
+	require.ErrorAs(t, err, &nfe)
+	err = ds.SetTeamsTenant(ctx, &t1.ID, []uint{team.ID})
+	require.ErrorAs(t, err, &nfe)
+}
+
+func testTenantsIsolation(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tenant1, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1"})
+	require.NoError(t, err)
+	tenant2, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant2"})
+	require.NoError(t, err)
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+	require.NoError(t, ds.SetTeamsTenant(ctx, &tenant1.ID, []uint{team1.ID}))
+	require.NoError(t, ds.SetTeamsTenant(ctx, &tenant2.ID, []uint{team2.ID}))
+
+	now := time.Now()
+	h1 := newDuplicateTestHost(t, ds, "S1", "U1", &team1.ID, now)
+	h2 := newDuplicateTestHost(t, ds, "S2", "U2", &team2.ID, now)
+	h3 := newDuplicateTestHost(t, ds, "S3", "U3", nil, now)
+
+	listHostIDs := func(user *fleet.User) []uint {
+		hosts, err := ds.ListHosts(ctx, fleet.TeamFilter{User: user}, fleet.HostListOptions{})
+		require.NoError(t, err)
+		var ids []uint
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+	listTeamIDs := func(user *fleet.User) []uint {
+		teams, err := ds.ListTeams(ctx, fleet.TeamFilter{User: user}, fleet.ListOptions{})
+		require.NoError(t, err)
+		var ids []uint
+		for _, tm := range teams {
+			ids = append(ids, tm.ID)
+		}
+		return ids
+	}
+
+	// a global admin without tenant sees everything
+	require.ElementsMatch(t, []uint{h1.ID, h2.ID, h3.ID}, listHostIDs(test.UserAdmin))
+	require.ElementsMatch(t, []uint{team1.ID, team2.ID}, listTeamIDs(test.UserAdmin))
+
+	// a global admin of a tenant only sees the tenant's hosts and teams
+	tenantAdmin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: &tenant1.ID}
+	require.ElementsMatch(t, []uint{h1.ID}, listHostIDs(tenantAdmin))
+	require.ElementsMatch(t, []uint{team1.ID}, listTeamIDs(tenantAdmin))
+
+	// a team admin of another tenant's team sees nothing
+	crossTenant := &fleet.User{
+		Teams:    []fleet.UserTeam{{Team: *team2, Role: fleet.RoleAdmin}},
+		TenantID: &tenant1.ID,
+	}
+	require.Empty(t, listHostIDs(crossTenant))
+	require.Empty(t, listTeamIDs(crossTenant))
+
+	// the teams of the tenant are loaded with its users, for authorization
+	user, err := ds.NewUser(ctx, &fleet.User{
+		Name:       "tenant user",
+		Email:      "tenant@example.com",
+		Password:   []byte("foo"),
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	})
+	require.NoError(t, err)
+	user, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, user.TenantID)
+	require.Empty(t, user.TenantTeamIDs)
+
+	require.NoError(t, ds.SetUsersTenant(ctx, &tenant1.ID, []uint{user.ID}))
+	team3, err := ds.NewTeam(ctx, &fleet.Team{Name: "team3"})
+	require.NoError(t, err)
+	require.NoError(t, ds.SetTeamsTenant(ctx, &tenant1.ID, []uint{team3.ID}))
+	user, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, &tenant1.ID, user.TenantID)
+	require.Equal(t, []uint{team1.ID, team3.ID}, user.TenantTeamIDs)
+}
+
+func testTenantAppConfig(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	appCfg, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	appCfg.OrgInfo.OrgName = "MSP"
+	require.NoError(t, ds.SaveAppConfig(ctx, appCfg))
+
+	tenant, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1"})
+	require.NoError(t, err)
+
+	tenantCfg, err := ds.TenantAppConfig(ctx, tenant.ID)
+	require.NoError(t, err)
+	require.Equal(t, "MSP", tenantCfg.OrgInfo.OrgName)
+
+	tenant.Config.OrgInfo = &fleet.OrgInfo{OrgName: "Customer"}
+	_, err = ds.SaveTenant(ctx, tenant)
+	require.NoError(t, err)
+
+	tenantCfg, err = ds.TenantAppConfig(ctx, tenant.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Customer", tenantCfg.OrgInfo.OrgName)
+
+	appCfg, err = ds.AppConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "MSP", appCfg.OrgInfo.OrgName)
+
+	var nfe fleet.NotFoundError
+	_, err = ds.TenantAppConfig(ctx, 999)
+	require.ErrorAs(t, err, &nfe)
+}
+
+func testTenantHostExpiry(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	appCfg, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	appCfg.HostExpirySettings = fleet.HostExpirySettings{HostExpiryEnabled: true, HostExpiryWindow: 30}
+	require.NoError(t, ds.SaveAppConfig(ctx, appCfg))
+
+	// tenant1 uses a shorter window, tenant2 disables host expiry and tenant3
+	// inherits the global settings
+	tenant1, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1", Config: fleet.TenantConfig{
+		HostExpirySettings: &fleet.HostExpirySettings{HostExpiryEnabled: true, HostExpiryWindow: 10},
+	}})
+	require.NoError(t, err)
+	tenant2, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant2", Config: fleet.TenantConfig{
+		HostExpirySettings: &fleet.HostExpirySettings{HostExpiryEnabled: false},
+	}})
+	require.NoError(t, err)
+	tenant3, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant3"})
+	require.NoError(t, err)
+
+	var teamIDs []uint
+	for i, tenant := range []*fleet.Tenant{tenant1, tenant2, tenant3} {
+		team, err := ds.NewTeam(ctx, &fleet.Team{Name: fmt.Sprintf("team%d", i+1)})
+		require.NoError(t, err)
+		require.NoError(t, ds.SetTeamsTenant(ctx, &tenant.ID, []uint{team.ID}))
+		teamIDs = append(teamIDs, team.ID)
+	}
+
+	// a team's own settings take precedence over its tenant's
+	team4, err := ds.NewTeam(ctx, &fleet.Team{Name: "team4", Config: fleet.TeamConfig{
+		HostExpirySettings: fleet.HostExpirySettings{HostExpiryEnabled: true, HostExpiryWindow: 50},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, ds.SetTeamsTenant(ctx, &tenant1.ID, []uint{team4.ID}))
+	teamIDs = append(teamIDs, team4.ID)
+
+	daysAgo := func(days int) time.Time {
+		return time.Now().Add(-time.Duration(days)*24*time.Hour - time.Hour)
+	}
+	var hostsByTeam [][]uint
+	for i, teamID := range append(teamIDs, 0) {
+		var tmID *uint
+		if teamID != 0 {
+			tmID = ptr.Uint(teamID)
+		}
+		var ids []uint
+		for _, days := range []int{20, 40} {
+			h := newDuplicateTestHost(t, ds, fmt.Sprintf("S%d-%d", i, days), fmt.Sprintf("U%d-%d", i, days), tmID, daysAgo(days))
+			ids = append(ids, h.ID)
+		}
+		hostsByTeam = append(hostsByTeam, ids)
+	}
+
+	deleted, err := ds.CleanupExpiredHosts(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []uint{
+		// tenant1 (10 days)
+		hostsByTeam[0][0], hostsByTeam[0][1],
+		// tenant2 (disabled): none
+		// tenant3 (global, 30 days)
+		hostsByTeam[2][1],
+		// team4 (50 days): none
+		// no team (global, 30 days)
+		hostsByTeam[4][1],
+	}, deleted)
+}
+
+func testTenantMDMAssets(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	tenant1, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1"})
+	require.NoError(t, err)
+	tenant2, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant2"})
+	require.NoError(t, err)
+
+	names := []fleet.TenantMDMAssetName{fleet.TenantMDMAssetAPNSCert, fleet.TenantMDMAssetAPNSKey}
+	assets, err := ds.GetTenantMDMAssets(ctx, tenant1.ID, names)
+	require.NoError(t, err)
+	require.Empty(t, assets)
+
+	err = ds.SetTenantMDMAssets(ctx, tenant1.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: []byte("cert1")},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: []byte("key1")},
+	})
+	require.NoError(t, err)
+	err = ds.SetTenantMDMAssets(ctx, tenant2.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: []byte("cert2")},
+	})
+	require.NoError(t, err)
+
+	assets, err = ds.GetTenantMDMAssets(ctx, tenant1.ID, names)
+	require.NoError(t, err)
+	require.Equal(t, map[fleet.TenantMDMAssetName][]byte{
+		fleet.TenantMDMAssetAPNSCert: []byte("cert1"),
+		fleet.TenantMDMAssetAPNSKey:  []byte("key1"),
+	}, assets)
+
+	// the assets are encrypted with the tenant's key, a tenant's assets cannot
+	// be decrypted with another tenant's key.
+	var raw []byte
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &raw, `SELECT value FROM tenant_mdm_assets WHERE tenant_id = ? AND name = ?`, tenant1.ID, fleet.TenantMDMAssetAPNSCert)
+	})
+	require.NotContains(t, string(raw), "cert1")
+	key2, err := ds.tenantEncryptionKeyDB(ctx, ds.reader(ctx), tenant2.ID, false)
+	require.NoError(t, err)
+	gcm2, err := newAESGCM(key2)
+	require.NoError(t, err)
+	_, err = openAESGCM(gcm2, raw)
+	require.Error(t, err)
+
+	// replace an asset
+	err = ds.SetTenantMDMAssets(ctx, tenant1.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: []byte("cert1-renewed")},
+	})
+	require.NoError(t, err)
+
+	// rotating the key keeps the assets readable
+	key1, err := ds.tenantEncryptionKeyDB(ctx, ds.reader(ctx), tenant1.ID, false)
+	require.NoError(t, err)
+	require.NoError(t, ds.RotateTenantEncryptionKey(ctx, tenant1.ID))
+	newKey1, err := ds.tenantEncryptionKeyDB(ctx, ds.reader(ctx), tenant1.ID, false)
+	require.NoError(t, err)
+	require.NotEqual(t, key1, newKey1)
+
+	assets, err = ds.GetTenantMDMAssets(ctx, tenant1.ID, names)
+	require.NoError(t, err)
+	require.Equal(t, map[fleet.TenantMDMAssetName][]byte{
+		fleet.TenantMDMAssetAPNSCert: []byte("cert1-renewed"),
+		fleet.TenantMDMAssetAPNSKey:  []byte("key1"),
+	}, assets)
+
+	assets, err = ds.GetTenantMDMAssets(ctx, tenant2.ID, names)
+	require.NoError(t, err)
+	require.Equal(t, map[fleet.TenantMDMAssetName][]byte{fleet.TenantMDMAssetAPNSCert: []byte("cert2")}, assets)
+
+	var nfe fleet.NotFoundError
+	err = ds.RotateTenantEncryptionKey(ctx, 999)
+	require.ErrorAs(t, err, &nfe)
+	_, err = ds.GetTenantMDMAssets(ctx, 999, names)
+	require.ErrorAs(t, err, &nfe)
+
+	// deleting the tenant deletes its assets
+	require.NoError(t, ds.DeleteTenant(ctx, tenant1.ID))
+	var count int
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &count, `SELECT COUNT(*) FROM tenant_mdm_assets WHERE tenant_id = ?`, tenant1.ID)
+	})
+	require.Zero(t, count)
+}
+
+func testTenantPushCert(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	globalCert, globalKey := apnsCertForTest(t, "com.apple.mgmt.Global")
+	storage, err := ds.NewMDMAppleMDMStorage(globalCert, globalKey)
+	require.NoError(t, err)
+
+	tenant1, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant1"})
+	require.NoError(t, err)
+	tenant2, err := ds.NewTenant(ctx, &fleet.Tenant{Name: "tenant2"})
+	require.NoError(t, err)
+
+	tenantCert, tenantKey := apnsCertForTest(t, "com.apple.mgmt.Tenant1")
+	err = ds.SetTenantMDMAssets(ctx, tenant1.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: tenantCert},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: tenantKey},
+	})
+	require.NoError(t, err)
+	// an invalid certificate does not prevent the other tenants' lookups
+	err = ds.SetTenantMDMAssets(ctx, tenant2.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: []byte("invalid")},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: []byte("invalid")},
+	})
+	require.NoError(t, err)
+
+	leafTopic := func(cert *tls.Certificate) string {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		topic, err := cryptoutil.TopicFromCert(leaf)
+		require.NoError(t, err)
+		return topic
+	}
+
+	// the tenant's certificate is used for its topic
+	cert, staleToken, err := storage.RetrievePushCert(ctx, "com.apple.mgmt.Tenant1")
+	require.NoError(t, err)
+	require.Equal(t, "com.apple.mgmt.Tenant1", leafTopic(cert))
+	stale, err := storage.IsPushCertStale(ctx, "com.apple.mgmt.Tenant1", staleToken)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	// the server's certificate is used for the other topics
+	cert, globalToken, err := storage.RetrievePushCert(ctx, "com.apple.mgmt.Global")
+	require.NoError(t, err)
+	require.Equal(t, "com.apple.mgmt.Global", leafTopic(cert))
+	stale, err = storage.IsPushCertStale(ctx, "com.apple.mgmt.Global", globalToken)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	// the tenant's certificate is stale once renewed
+	renewedCert, renewedKey := apnsCertForTest(t, "com.apple.mgmt.Tenant1")
+	err = ds.SetTenantMDMAssets(ctx, tenant1.ID, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: renewedCert},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: renewedKey},
+	})
+	require.NoError(t, err)
+	stale, err = storage.IsPushCertStale(ctx, "com.apple.mgmt.Tenant1", staleToken)
+	require.NoError(t, err)
+	require.True(t, stale)
+	_, renewedToken, err := storage.RetrievePushCert(ctx, "com.apple.mgmt.Tenant1")
+	require.NoError(t, err)
+	require.NotEqual(t, staleToken, renewedToken)
+
+	// and once the tenant is deleted
+	require.NoError(t, ds.DeleteTenant(ctx, tenant1.ID))
+	stale, err = storage.IsPushCertStale(ctx, "com.apple.mgmt.Tenant1", renewedToken)
+	require.NoError(t, err)
+	require.True(t, stale)
+}
+
+// apnsCertForTest returns a self-signed certificate and key in PEM with the
+// APNs topic as UID, like the APNs certificates issued by Apple.
+func apnsCertForTest(t *testing.T, topic string) (certPEM, keyPEM []byte) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			ExtraNames: []pkix.AttributeTypeAndValue{
+				{Type: asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}, Value: topic},
+			},
+		},
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	return certPEM, keyPEM
+}
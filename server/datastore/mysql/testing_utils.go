@@ -28,6 +28,7 @@ const (
 	testPassword              = "toor"
 	testAddress               = "localhost:3307"
 	testReplicaDatabaseSuffix = "_replica"
+	testServerPrivateKey      = "ZEBZsC2bCkYgaLWwdCqBbmaYm3OKnRQS"
 )
 
 func connectMySQL(t testing.TB, testName string, opts *DatastoreTestOptions) *Datastore {
@@ -56,6 +57,7 @@ func connectMySQL(t testing.TB, testName string, opts *DatastoreTestOptions) *Da
 	// https://dev.mysql.com/doc/refman/8.0/en/sql-mode.html#sqlmode_ansi
 	ds, err := New(config, clock.NewMockClock(), Logger(log.NewNopLogger()), LimitAttempts(1), replicaOpt, SQLMode("ANSI"))
 	require.Nil(t, err)
+	ds.serverPrivateKey = testServerPrivateKey
 
 	if opts.Replica {
 		setupReadReplica(t, testName, ds, opts)
@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+type translationsRow struct {
+	Locale    string          `db:"locale"`
+	Messages  json.RawMessage `db:"messages"`
+	UpdatedAt time.Time       `db:"updated_at"`
+}
+
+func (r translationsRow) toTranslations() (*fleet.Translations, error) {
+	t := &fleet.Translations{Locale: r.Locale, UpdatedAt: r.UpdatedAt}
+	if err := json.Unmarshal(r.Messages, &t.Messages); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (ds *Datastore) ListTranslations(ctx context.Context) ([]*fleet.Translations, error) {
+	var rows []translationsRow
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, `SELECT locale, messages, updated_at FROM translations ORDER BY locale`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list translations")
+	}
+
+	translations := make([]*fleet.Translations, 0, len(rows))
+	for _, r := range rows {
+		t, err := r.toTranslations()
+		if err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "unmarshal translations for locale %s", r.Locale)
+		}
+		translations = append(translations, t)
+	}
+	return translations, nil
+}
+
+func (ds *Datastore) Translations(ctx context.Context, locale string) (*fleet.Translations, error) {
+	return ds.translations(ctx, ds.reader(ctx), locale)
+}
+
+func (ds *Datastore) translations(ctx context.Context, q sqlx.QueryerContext, locale string) (*fleet.Translations, error) {
+	var r translationsRow
+	if err := sqlx.GetContext(ctx, q, &r, `SELECT locale, messages, updated_at FROM translations WHERE locale = ?`, locale); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("Translations").WithName(locale))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get translations")
+	}
+
+	t, err := r.toTranslations()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal translations")
+	}
+	return t, nil
+}
+
+func (ds *Datastore) ApplyTranslations(ctx context.Context, t *fleet.Translations) (*fleet.Translations, error) {
+	messages := t.Messages
+	if messages == nil {
+		messages = map[fleet.MessageKey]string{}
+	}
+	b, err := json.Marshal(messages)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal translations")
+	}
+
+	const stmt = `
+		INSERT INTO translations (locale, messages)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE
+			messages = VALUES(messages)
+	`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, t.Locale, b); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "apply translations")
+	}
+	return ds.translations(ctx, ds.writer(ctx), t.Locale)
+}
+
+func (ds *Datastore) DeleteTranslations(ctx context.Context, locale string) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM translations WHERE locale = ?`, locale)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete translations")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("Translations").WithName(locale))
+	}
+	return nil
+}
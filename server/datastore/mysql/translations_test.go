@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslations(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testTranslationsCRUD},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testTranslationsCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	list, err := ds.ListTranslations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	_, err = ds.Translations(ctx, "fr")
+	require.True(t, fleet.IsNotFound(err))
+
+	fr, err := ds.ApplyTranslations(ctx, &fleet.Translations{
+		Locale: "fr",
+		Messages: map[fleet.MessageKey]string{
+			fleet.MessageDesktopMyDevice:     "Mon appareil",
+			fleet.MessageDesktopTransparency: "Transparence",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "fr", fr.Locale)
+	require.Len(t, fr.Messages, 2)
+	require.Equal(t, "Mon appareil", fr.Messages[fleet.MessageDesktopMyDevice])
+	require.False(t, fr.UpdatedAt.IsZero())
+
+	_, err = ds.ApplyTranslations(ctx, &fleet.Translations{Locale: "de"})
+	require.NoError(t, err)
+
+	// replace the french translations
+	fr, err = ds.ApplyTranslations(ctx, &fleet.Translations{
+		Locale: "fr",
+		Messages: map[fleet.MessageKey]string{
+			fleet.MessageDesktopMyDevice: "Mon ordinateur",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[fleet.MessageKey]string{fleet.MessageDesktopMyDevice: "Mon ordinateur"}, fr.Messages)
+
+	list, err = ds.ListTranslations(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "de", list[0].Locale)
+	require.Empty(t, list[0].Messages)
+	require.Equal(t, "fr", list[1].Locale)
+	require.Equal(t, fr.Messages, list[1].Messages)
+
+	got, err := ds.Translations(ctx, "fr")
+	require.NoError(t, err)
+	require.Equal(t, fr.Messages, got.Messages)
+
+	require.NoError(t, ds.DeleteTranslations(ctx, "fr"))
+	_, err = ds.Translations(ctx, "fr")
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.DeleteTranslations(ctx, "fr")
+	require.True(t, fleet.IsNotFound(err))
+
+	list, err = ds.ListTranslations(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+}
@@ -0,0 +1,113 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// usageAnalyticsBatchSize is the number of API request counts inserted per
+// statement.
+const usageAnalyticsBatchSize = 500
+
+func (ds *Datastore) IncrementUsageAnalyticsAPIRequests(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error {
+	for len(counts) > 0 {
+		batch := counts
+		if len(batch) > usageAnalyticsBatchSize {
+			batch = batch[:usageAnalyticsBatchSize]
+		}
+		counts = counts[len(batch):]
+
+		args := make([]interface{}, 0, len(batch)*4)
+		for _, c := range batch {
+			args = append(args, c.Date.UTC().Format("2006-01-02"), c.Method, c.Path, c.Count)
+		}
+		stmt := fmt.Sprintf(`
+			INSERT INTO usage_analytics_api_requests (date, method, path, request_count)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE request_count = request_count + VALUES(request_count)`,
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?),", len(batch)), ","),
+		)
+		if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "increment usage analytics api requests")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) RecordUsageAnalyticsHostCount(ctx context.Context, date time.Time) error {
+	const stmt = `
+		INSERT INTO usage_analytics_host_counts (date, num_hosts)
+		SELECT ?, COUNT(*) FROM hosts
+		ON DUPLICATE KEY UPDATE num_hosts = VALUES(num_hosts)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, date.UTC().Format("2006-01-02")); err != nil {
+		return ctxerr.Wrap(ctx, err, "record usage analytics host count")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupUsageAnalytics(ctx context.Context, olderThan time.Time) error {
+	day := olderThan.UTC().Format("2006-01-02")
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM usage_analytics_api_requests WHERE date < ?`, day); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup usage analytics api requests")
+	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM usage_analytics_host_counts WHERE date < ?`, day); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup usage analytics host counts")
+	}
+	return nil
+}
+
+func (ds *Datastore) GetUsageAnalyticsDashboard(ctx context.Context, since time.Time, config config.FleetConfig) (*fleet.UsageAnalyticsDashboard, error) {
+	since = since.UTC().Truncate(24 * time.Hour)
+	day := since.Format("2006-01-02")
+
+	var stats fleet.StatisticsPayload
+	if err := ds.computeStatistics(ctx, &stats, config, time.Now().Add(-7*24*time.Hour)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "compute usage analytics features")
+	}
+
+	dashboard := &fleet.UsageAnalyticsDashboard{
+		Since:                 since,
+		Features:              fleet.UsageAnalyticsFeaturesFromStatistics(stats),
+		APIRequestsByDay:      []fleet.UsageAnalyticsAPIRequests{},
+		APIRequestsByEndpoint: []fleet.UsageAnalyticsEndpointRequests{},
+		HostCounts:            []fleet.UsageAnalyticsHostCount{},
+	}
+
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &dashboard.APIRequestsByDay, `
+		SELECT date, SUM(request_count) AS request_count
+		FROM usage_analytics_api_requests
+		WHERE date >= ?
+		GROUP BY date
+		ORDER BY date`, day,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select usage analytics api requests by day")
+	}
+
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &dashboard.APIRequestsByEndpoint, `
+		SELECT method, path, SUM(request_count) AS request_count
+		FROM usage_analytics_api_requests
+		WHERE date >= ?
+		GROUP BY method, path
+		ORDER BY request_count DESC, path, method`, day,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select usage analytics api requests by endpoint")
+	}
+
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &dashboard.HostCounts, `
+		SELECT date, num_hosts
+		FROM usage_analytics_host_counts
+		WHERE date >= ?
+		ORDER BY date`, day,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select usage analytics host counts")
+	}
+
+	return dashboard, nil
+}
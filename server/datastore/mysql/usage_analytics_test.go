@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageAnalytics(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	day1 := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	// the counts of the Fleet instances are added up
+	for i := 0; i < 2; i++ {
+		require.NoError(t, ds.IncrementUsageAnalyticsAPIRequests(ctx, []fleet.UsageAnalyticsAPIRequestCount{
+			{Date: day1, Method: "GET", Path: "/api/_version_/fleet/hosts", Count: 5},
+			{Date: day2, Method: "GET", Path: "/api/_version_/fleet/hosts", Count: 1},
+			{Date: day2, Method: "DELETE", Path: "/api/_version_/fleet/hosts/{id}", Count: 2},
+			{Date: day3, Method: "GET", Path: "/api/_version_/fleet/hosts/{id}", Count: 3},
+		}))
+	}
+	require.NoError(t, ds.IncrementUsageAnalyticsAPIRequests(ctx, nil))
+
+	test.NewHost(t, ds, "host1", "", "key1", "uuid1", time.Now())
+	require.NoError(t, ds.RecordUsageAnalyticsHostCount(ctx, day2))
+	test.NewHost(t, ds, "host2", "", "key2", "uuid2", time.Now())
+	require.NoError(t, ds.RecordUsageAnalyticsHostCount(ctx, day3))
+	// the count of the day is updated
+	test.NewHost(t, ds, "host3", "", "key3", "uuid3", time.Now())
+	require.NoError(t, ds.RecordUsageAnalyticsHostCount(ctx, day3.Add(time.Hour)))
+
+	dashboard, err := ds.GetUsageAnalyticsDashboard(ctx, day2.Add(12*time.Hour), config.FleetConfig{})
+	require.NoError(t, err)
+	require.Equal(t, day2, dashboard.Since)
+	require.Equal(t, 3, dashboard.Features.NumHostsEnrolled)
+	require.Equal(t, []fleet.UsageAnalyticsAPIRequests{
+		{Date: day2, Count: 6},
+		{Date: day3, Count: 6},
+	}, dashboard.APIRequestsByDay)
+	require.Equal(t, []fleet.UsageAnalyticsEndpointRequests{
+		{Method: "GET", Path: "/api/_version_/fleet/hosts/{id}", Count: 6},
+		{Method: "DELETE", Path: "/api/_version_/fleet/hosts/{id}", Count: 4},
+		{Method: "GET", Path: "/api/_version_/fleet/hosts", Count: 2},
+	}, dashboard.APIRequestsByEndpoint)
+	require.Equal(t, []fleet.UsageAnalyticsHostCount{
+		{Date: day2, NumHosts: 1},
+		{Date: day3, NumHosts: 3},
+	}, dashboard.HostCounts)
+
+	require.NoError(t, ds.CleanupUsageAnalytics(ctx, day3))
+	dashboard, err = ds.GetUsageAnalyticsDashboard(ctx, day1, config.FleetConfig{})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.UsageAnalyticsAPIRequests{{Date: day3, Count: 6}}, dashboard.APIRequestsByDay)
+	require.Equal(t, []fleet.UsageAnalyticsHostCount{{Date: day3, NumHosts: 3}}, dashboard.HostCounts)
+}
@@ -0,0 +1,329 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// syncUserMFAEnabled sets the users.mfa_enabled flag based on the confirmed
+// second factors of the user. It must be called after any change to those
+// factors.
+func syncUserMFAEnabled(ctx context.Context, tx sqlx.ExtContext, userID uint) error {
+	const stmt = `
+		UPDATE users
+		SET mfa_enabled = (
+			EXISTS (SELECT 1 FROM user_totp_secrets WHERE user_id = ? AND confirmed_at IS NOT NULL) OR
+			EXISTS (SELECT 1 FROM user_webauthn_credentials WHERE user_id = ?)
+		)
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, stmt, userID, userID, userID); err != nil {
+		return ctxerr.Wrap(ctx, err, "updating user mfa_enabled")
+	}
+	return nil
+}
+
+func (ds *Datastore) SetUserTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	encrypted, err := encrypt([]byte(secret), ds.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypting user totp secret")
+	}
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		const stmt = `
+			INSERT INTO user_totp_secrets (user_id, encrypted_secret)
+			VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE
+				encrypted_secret = VALUES(encrypted_secret),
+				confirmed_at = NULL,
+				last_used_step = 0,
+				created_at = CURRENT_TIMESTAMP
+		`
+		if _, err := tx.ExecContext(ctx, stmt, userID, encrypted); err != nil {
+			return ctxerr.Wrap(ctx, err, "inserting user totp secret")
+		}
+		return syncUserMFAEnabled(ctx, tx, userID)
+	})
+}
+
+func (ds *Datastore) UserTOTPSecret(ctx context.Context, userID uint) (*fleet.UserTOTPSecret, error) {
+	const stmt = `
+		SELECT user_id, encrypted_secret, confirmed_at, last_used_step, created_at
+		FROM user_totp_secrets
+		WHERE user_id = ?
+	`
+	var row struct {
+		fleet.UserTOTPSecret
+		Encrypted []byte `db:"encrypted_secret"`
+	}
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &row, stmt, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("UserTOTPSecret").WithID(userID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting user totp secret")
+	}
+
+	decrypted, err := decrypt(row.Encrypted, ds.serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decrypting user totp secret")
+	}
+	secret := row.UserTOTPSecret
+	secret.Secret = string(decrypted)
+	return &secret, nil
+}
+
+func (ds *Datastore) ConfirmUserTOTPSecret(ctx context.Context, userID uint, step int64) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		const stmt = `
+			UPDATE user_totp_secrets
+			SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = ?
+			WHERE user_id = ?
+		`
+		res, err := tx.ExecContext(ctx, stmt, step, userID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "confirming user totp secret")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ctxerr.Wrap(ctx, notFound("UserTOTPSecret").WithID(userID))
+		}
+		return syncUserMFAEnabled(ctx, tx, userID)
+	})
+}
+
+func (ds *Datastore) MarkUserTOTPStepUsed(ctx context.Context, userID uint, step int64) (bool, error) {
+	// the condition on last_used_step makes this safe against concurrent
+	// logins with the same code
+	const stmt = `
+		UPDATE user_totp_secrets
+		SET last_used_step = ?
+		WHERE user_id = ? AND last_used_step < ?
+	`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, step, userID, step)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "updating user totp last used step")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ds *Datastore) DeleteUserTOTPSecret(ctx context.Context, userID uint) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_totp_secrets WHERE user_id = ?`, userID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "deleting user totp secret")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ctxerr.Wrap(ctx, notFound("UserTOTPSecret").WithID(userID))
+		}
+		return syncUserMFAEnabled(ctx, tx, userID)
+	})
+}
+
+const userWebAuthnCredentialSelectColumns = `
+	id,
+	user_id,
+	name,
+	credential_id,
+	public_key,
+	sign_count,
+	created_at,
+	last_used_at
+`
+
+func (ds *Datastore) NewUserWebAuthnCredential(ctx context.Context, cred *fleet.UserWebAuthnCredential) (*fleet.UserWebAuthnCredential, error) {
+	var id int64
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		const stmt = `
+			INSERT INTO user_webauthn_credentials (
+				user_id,
+				name,
+				credential_id,
+				public_key,
+				sign_count
+			)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		res, err := tx.ExecContext(ctx, stmt, cred.UserID, cred.Name, cred.CredentialID, cred.PublicKey, cred.SignCount)
+		if err != nil {
+			if isDuplicate(err) {
+				return ctxerr.Wrap(ctx, alreadyExists("UserWebAuthnCredential", cred.Name))
+			}
+			return ctxerr.Wrap(ctx, err, "inserting user webauthn credential")
+		}
+		id, _ = res.LastInsertId() // cannot fail with the mysql driver
+		return syncUserMFAEnabled(ctx, tx, cred.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `SELECT ` + userWebAuthnCredentialSelectColumns + ` FROM user_webauthn_credentials WHERE id = ?`
+	var created fleet.UserWebAuthnCredential
+	if err := sqlx.GetContext(ctx, ds.writer(ctx), &created, stmt, id); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting created user webauthn credential")
+	}
+	return &created, nil
+}
+
+func (ds *Datastore) ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*fleet.UserWebAuthnCredential, error) {
+	stmt := `SELECT ` + userWebAuthnCredentialSelectColumns + ` FROM user_webauthn_credentials WHERE user_id = ? ORDER BY id`
+	var creds []*fleet.UserWebAuthnCredential
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &creds, stmt, userID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing user webauthn credentials")
+	}
+	return creds, nil
+}
+
+func (ds *Datastore) MarkUserWebAuthnCredentialUsed(ctx context.Context, id uint, signCount uint32) error {
+	const stmt = `
+		UPDATE user_webauthn_credentials
+		SET sign_count = ?, last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, signCount, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "updating user webauthn credential last used")
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteUserWebAuthnCredential(ctx context.Context, userID, id uint) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_webauthn_credentials WHERE id = ? AND user_id = ?`, id, userID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "deleting user webauthn credential")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ctxerr.Wrap(ctx, notFound("UserWebAuthnCredential").WithID(id))
+		}
+		return syncUserMFAEnabled(ctx, tx, userID)
+	})
+}
+
+func (ds *Datastore) ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return ctxerr.Wrap(ctx, err, "deleting user mfa recovery codes")
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+
+		stmt := `INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES `
+		args := make([]any, 0, 2*len(codeHashes))
+		for i, h := range codeHashes {
+			if i > 0 {
+				stmt += `, `
+			}
+			stmt += `(?, ?)`
+			args = append(args, userID, h)
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "inserting user mfa recovery codes")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) UseUserMFARecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	const stmt = `
+		UPDATE user_mfa_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, userID, codeHash)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "using user mfa recovery code")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("UserMFARecoveryCode").WithName("<code redacted>"))
+	}
+	return nil
+}
+
+func (ds *Datastore) CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	const stmt = `SELECT COUNT(*) FROM user_mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`
+	var count int
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &count, stmt, userID); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "counting user mfa recovery codes")
+	}
+	return count, nil
+}
+
+func (ds *Datastore) DeleteUserMFA(ctx context.Context, userID uint) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		for _, stmt := range []string{
+			`DELETE FROM user_totp_secrets WHERE user_id = ?`,
+			`DELETE FROM user_webauthn_credentials WHERE user_id = ?`,
+			`DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`,
+			`DELETE FROM mfa_challenges WHERE user_id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, userID); err != nil {
+				return ctxerr.Wrap(ctx, err, "deleting user mfa")
+			}
+		}
+		return syncUserMFAEnabled(ctx, tx, userID)
+	})
+}
+
+func (ds *Datastore) NewMFAChallenge(ctx context.Context, challenge *fleet.MFAChallenge) error {
+	const stmt = `
+		INSERT INTO mfa_challenges (token, user_id, purpose, webauthn_challenge)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, challenge.Token, challenge.UserID, challenge.Purpose, challenge.WebAuthnChallenge); err != nil {
+		return ctxerr.Wrap(ctx, err, "inserting mfa challenge")
+	}
+	return nil
+}
+
+func (ds *Datastore) MFAChallenge(ctx context.Context, token string) (*fleet.MFAChallenge, error) {
+	const stmt = `
+		SELECT token, user_id, purpose, webauthn_challenge, created_at, attempts
+		FROM mfa_challenges
+		WHERE token = ?
+	`
+	// read from the primary, the challenge is typically created right before
+	var challenge fleet.MFAChallenge
+	if err := sqlx.GetContext(ctx, ds.writer(ctx), &challenge, stmt, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("MFAChallenge").WithName("<token redacted>"))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting mfa challenge")
+	}
+	return &challenge, nil
+}
+
+func (ds *Datastore) RecordMFAChallengeAttempt(ctx context.Context, token string, maxAttempts int) (bool, error) {
+	// the condition on attempts makes this safe against concurrent attempts
+	// with the same challenge
+	const stmt = `
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE token = ? AND attempts < ?
+	`
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, token, maxAttempts)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "recording mfa challenge attempt")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ds *Datastore) DeleteMFAChallenge(ctx context.Context, token string) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM mfa_challenges WHERE token = ?`, token); err != nil {
+		return ctxerr.Wrap(ctx, err, "deleting mfa challenge")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupExpiredMFAChallenges(ctx context.Context) error {
+	const stmt = `DELETE FROM mfa_challenges WHERE created_at < DATE_SUB(NOW(), INTERVAL ? SECOND)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, int(fleet.MFAChallengeExpiry.Seconds())); err != nil {
+		return ctxerr.Wrap(ctx, err, "deleting expired mfa challenges")
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserMFA(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"TOTP", testUserMFATOTP},
+		{"WebAuthn", testUserMFAWebAuthn},
+		{"RecoveryCodes", testUserMFARecoveryCodes},
+		{"Challenges", testUserMFAChallenges},
+		{"DeleteUserMFA", testUserMFADelete},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func newMFATestUser(t *testing.T, ds *Datastore, email string) *fleet.User {
+	user, err := ds.NewUser(context.Background(), &fleet.User{
+		Password:   []byte("supersecret"),
+		Email:      email,
+		GlobalRole: ptr.String(fleet.RoleObserver),
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func requireUserMFAEnabled(t *testing.T, ds *Datastore, userID uint, want bool) {
+	user, err := ds.UserByID(context.Background(), userID)
+	require.NoError(t, err)
+	require.Equal(t, want, user.MFAEnabled)
+}
+
+func testUserMFATOTP(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := newMFATestUser(t, ds, "totp@example.com")
+
+	_, err := ds.UserTOTPSecret(ctx, user.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.ConfirmUserTOTPSecret(ctx, user.ID, 1)))
+	require.True(t, fleet.IsNotFound(ds.DeleteUserTOTPSecret(ctx, user.ID)))
+
+	// an unconfirmed secret does not enable MFA
+	require.NoError(t, ds.SetUserTOTPSecret(ctx, user.ID, "SECRET1"))
+	secret, err := ds.UserTOTPSecret(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "SECRET1", secret.Secret)
+	require.Nil(t, secret.ConfirmedAt)
+	requireUserMFAEnabled(t, ds, user.ID, false)
+
+	require.NoError(t, ds.ConfirmUserTOTPSecret(ctx, user.ID, 100))
+	secret, err = ds.UserTOTPSecret(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, secret.ConfirmedAt)
+	require.EqualValues(t, 100, secret.LastUsedStep)
+	requireUserMFAEnabled(t, ds, user.ID, true)
+
+	// steps can only move forward
+	ok, err := ds.MarkUserTOTPStepUsed(ctx, user.ID, 100)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = ds.MarkUserTOTPStepUsed(ctx, user.ID, 101)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = ds.MarkUserTOTPStepUsed(ctx, user.ID, 99)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// replacing the secret resets the confirmation
+	require.NoError(t, ds.SetUserTOTPSecret(ctx, user.ID, "SECRET2"))
+	secret, err = ds.UserTOTPSecret(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "SECRET2", secret.Secret)
+	require.Nil(t, secret.ConfirmedAt)
+	require.Zero(t, secret.LastUsedStep)
+	requireUserMFAEnabled(t, ds, user.ID, false)
+
+	// the secret is encrypted at rest
+	var stored []byte
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &stored,
+		`SELECT encrypted_secret FROM user_totp_secrets WHERE user_id = ?`, user.ID))
+	require.NotEmpty(t, stored)
+	require.NotContains(t, string(stored), "SECRET2")
+
+	require.NoError(t, ds.ConfirmUserTOTPSecret(ctx, user.ID, 200))
+	requireUserMFAEnabled(t, ds, user.ID, true)
+	require.NoError(t, ds.DeleteUserTOTPSecret(ctx, user.ID))
+	requireUserMFAEnabled(t, ds, user.ID, false)
+	_, err = ds.UserTOTPSecret(ctx, user.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testUserMFAWebAuthn(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user1 := newMFATestUser(t, ds, "webauthn1@example.com")
+	user2 := newMFATestUser(t, ds, "webauthn2@example.com")
+
+	creds, err := ds.ListUserWebAuthnCredentials(ctx, user1.ID)
+	require.NoError(t, err)
+	require.Empty(t, creds)
+
+	cred1, err := ds.NewUserWebAuthnCredential(ctx, &fleet.UserWebAuthnCredential{
+		UserID:       user1.ID,
+		Name:         "yubikey",
+		CredentialID: []byte("cred1"),
+		PublicKey:    []byte("pub1"),
+		SignCount:    3,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, cred1.ID)
+	require.Equal(t, "yubikey", cred1.Name)
+	require.Equal(t, []byte("cred1"), cred1.CredentialID)
+	require.Equal(t, []byte("pub1"), cred1.PublicKey)
+	require.EqualValues(t, 3, cred1.SignCount)
+	require.Nil(t, cred1.LastUsedAt)
+	requireUserMFAEnabled(t, ds, user1.ID, true)
+
+	cred2, err := ds.NewUserWebAuthnCredential(ctx, &fleet.UserWebAuthnCredential{
+		UserID:       user1.ID,
+		Name:         "laptop",
+		CredentialID: []byte("cred2"),
+		PublicKey:    []byte("pub2"),
+	})
+	require.NoError(t, err)
+
+	// the credential id is globally unique
+	_, err = ds.NewUserWebAuthnCredential(ctx, &fleet.UserWebAuthnCredential{
+		UserID:       user2.ID,
+		Name:         "dup",
+		CredentialID: []byte("cred1"),
+		PublicKey:    []byte("pub3"),
+	})
+	require.Error(t, err)
+	var existsErr fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &existsErr)
+	requireUserMFAEnabled(t, ds, user2.ID, false)
+
+	require.NoError(t, ds.MarkUserWebAuthnCredentialUsed(ctx, cred1.ID, 10))
+	creds, err = ds.ListUserWebAuthnCredentials(ctx, user1.ID)
+	require.NoError(t, err)
+	require.Len(t, creds, 2)
+	require.Equal(t, cred1.ID, creds[0].ID)
+	require.EqualValues(t, 10, creds[0].SignCount)
+	require.NotNil(t, creds[0].LastUsedAt)
+	require.WithinDuration(t, time.Now(), *creds[0].LastUsedAt, time.Minute)
+	require.Equal(t, cred2.ID, creds[1].ID)
+
+	// cannot delete the credential of another user
+	err = ds.DeleteUserWebAuthnCredential(ctx, user2.ID, cred1.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.DeleteUserWebAuthnCredential(ctx, user1.ID, cred1.ID))
+	requireUserMFAEnabled(t, ds, user1.ID, true)
+	require.NoError(t, ds.DeleteUserWebAuthnCredential(ctx, user1.ID, cred2.ID))
+	requireUserMFAEnabled(t, ds, user1.ID, false)
+}
+
+func testUserMFARecoveryCodes(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := newMFATestUser(t, ds, "recovery@example.com")
+
+	count, err := ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, []string{"a", "b", "c"}))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	require.NoError(t, ds.UseUserMFARecoveryCode(ctx, user.ID, "b"))
+	// a code can only be used once
+	require.True(t, fleet.IsNotFound(ds.UseUserMFARecoveryCode(ctx, user.ID, "b")))
+	require.True(t, fleet.IsNotFound(ds.UseUserMFARecoveryCode(ctx, user.ID, "z")))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// replacing the codes invalidates the old ones
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, []string{"d", "e"}))
+	require.True(t, fleet.IsNotFound(ds.UseUserMFARecoveryCode(ctx, user.ID, "a")))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, nil))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func testUserMFAChallenges(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := newMFATestUser(t, ds, "challenge@example.com")
+
+	_, err := ds.MFAChallenge(ctx, "nope")
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.NewMFAChallenge(ctx, &fleet.MFAChallenge{
+		Token:             "tok1",
+		UserID:            user.ID,
+		Purpose:           fleet.MFAChallengePurposeLogin,
+		WebAuthnChallenge: []byte("challenge"),
+	}))
+	require.NoError(t, ds.NewMFAChallenge(ctx, &fleet.MFAChallenge{
+		Token:   "tok2",
+		UserID:  user.ID,
+		Purpose: fleet.MFAChallengePurposeWebAuthnRegistration,
+	}))
+
+	c, err := ds.MFAChallenge(ctx, "tok1")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, c.UserID)
+	require.Equal(t, fleet.MFAChallengePurposeLogin, c.Purpose)
+	require.Equal(t, []byte("challenge"), c.WebAuthnChallenge)
+	require.False(t, c.Expired(time.Now()))
+
+	c, err = ds.MFAChallenge(ctx, "tok2")
+	require.NoError(t, err)
+	require.Nil(t, c.WebAuthnChallenge)
+	require.Zero(t, c.Attempts)
+
+	// attempts are limited
+	for i := 0; i < 2; i++ {
+		ok, err := ds.RecordMFAChallengeAttempt(ctx, "tok2", 2)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	ok, err := ds.RecordMFAChallengeAttempt(ctx, "tok2", 2)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = ds.RecordMFAChallengeAttempt(ctx, "nope", 2)
+	require.NoError(t, err)
+	require.False(t, ok)
+	c, err = ds.MFAChallenge(ctx, "tok2")
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Attempts)
+
+	require.NoError(t, ds.DeleteMFAChallenge(ctx, "tok1"))
+	_, err = ds.MFAChallenge(ctx, "tok1")
+	require.True(t, fleet.IsNotFound(err))
+
+	// only expired challenges are cleaned up
+	require.NoError(t, ds.CleanupExpiredMFAChallenges(ctx))
+	_, err = ds.MFAChallenge(ctx, "tok2")
+	require.NoError(t, err)
+
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE mfa_challenges SET created_at = DATE_SUB(NOW(), INTERVAL 10 MINUTE) WHERE token = 'tok2'`)
+		return err
+	})
+	require.NoError(t, ds.CleanupExpiredMFAChallenges(ctx))
+	_, err = ds.MFAChallenge(ctx, "tok2")
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testUserMFADelete(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := newMFATestUser(t, ds, "delete@example.com")
+
+	require.NoError(t, ds.SetUserTOTPSecret(ctx, user.ID, "SECRET"))
+	require.NoError(t, ds.ConfirmUserTOTPSecret(ctx, user.ID, 1))
+	_, err := ds.NewUserWebAuthnCredential(ctx, &fleet.UserWebAuthnCredential{
+		UserID:       user.ID,
+		Name:         "key",
+		CredentialID: []byte("cred"),
+		PublicKey:    []byte("pub"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, []string{"a"}))
+	require.NoError(t, ds.NewMFAChallenge(ctx, &fleet.MFAChallenge{Token: "tok", UserID: user.ID, Purpose: fleet.MFAChallengePurposeLogin}))
+	requireUserMFAEnabled(t, ds, user.ID, true)
+
+	require.NoError(t, ds.DeleteUserMFA(ctx, user.ID))
+	requireUserMFAEnabled(t, ds, user.ID, false)
+
+	_, err = ds.UserTOTPSecret(ctx, user.ID)
+	require.True(t, fleet.IsNotFound(err))
+	creds, err := ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, creds)
+	count, err := ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	_, err = ds.MFAChallenge(ctx, "tok")
+	require.True(t, fleet.IsNotFound(err))
+}
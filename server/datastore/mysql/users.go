@@ -32,8 +32,9 @@ func (ds *Datastore) NewUser(ctx context.Context, user *fleet.User) (*fleet.User
       	position,
         sso_enabled,
 		api_only,
-		global_role
-      ) VALUES (?,?,?,?,?,?,?,?,?,?)
+		global_role,
+		tenant_id
+      ) VALUES (?,?,?,?,?,?,?,?,?,?,?)
       `
 		result, err := tx.ExecContext(ctx, sqlStatement,
 			user.Password,
@@ -45,7 +46,8 @@ func (ds *Datastore) NewUser(ctx context.Context, user *fleet.User) (*fleet.User
 			user.Position,
 			user.SSOEnabled,
 			user.APIOnly,
-			user.GlobalRole)
+			user.GlobalRole,
+			user.TenantID)
 		if err != nil {
 			return ctxerr.Wrap(ctx, err, "create new user")
 		}
@@ -107,6 +109,10 @@ func (ds *Datastore) ListUsers(ctx context.Context, opt fleet.UserListOptions) (
 		sqlStatement += " AND id IN (SELECT user_id FROM user_teams WHERE team_id = ?)"
 		params = append(params, opt.TeamID)
 	}
+	if opt.TenantID != nil {
+		sqlStatement += " AND tenant_id = ?"
+		params = append(params, *opt.TenantID)
+	}
 
 	sqlStatement, params = searchLike(sqlStatement, params, opt.MatchQuery, userSearchColumns...)
 	sqlStatement, params = appendListOptionsWithCursorToSQL(sqlStatement, params, &opt.ListOptions)
@@ -238,6 +244,43 @@ func (ds *Datastore) loadTeamsForUsers(ctx context.Context, users []*fleet.User)
 		user.Teams = append(user.Teams, r.UserTeam)
 	}
 
+	return ds.loadTenantTeamsForUsers(ctx, users)
+}
+
+// loadTenantTeamsForUsers loads the IDs of the teams of the tenant of the
+// provided users, for the users that belong to a tenant.
+func (ds *Datastore) loadTenantTeamsForUsers(ctx context.Context, users []*fleet.User) error {
+	var tenantIDs []uint
+	for _, u := range users {
+		if u.TenantID != nil {
+			tenantIDs = append(tenantIDs, *u.TenantID)
+		}
+	}
+	if len(tenantIDs) == 0 {
+		return nil
+	}
+
+	stmt, args, err := sqlx.In(`SELECT id, tenant_id FROM teams WHERE tenant_id IN (?) ORDER BY id`, tenantIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "sqlx.In loadTenantTeamsForUsers")
+	}
+	var rows []struct {
+		ID       uint `db:"id"`
+		TenantID uint `db:"tenant_id"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "get loadTenantTeamsForUsers")
+	}
+
+	teamIDsByTenant := make(map[uint][]uint)
+	for _, r := range rows {
+		teamIDsByTenant[r.TenantID] = append(teamIDsByTenant[r.TenantID], r.ID)
+	}
+	for _, u := range users {
+		if u.TenantID != nil {
+			u.TenantTeamIDs = teamIDsByTenant[*u.TenantID]
+		}
+	}
 	return nil
 }
 
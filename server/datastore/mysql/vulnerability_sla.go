@@ -0,0 +1,139 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostCVEsStmt selects the vulnerabilities currently detected on the hosts,
+// in their software or in their operating system.
+const hostCVEsStmt = `
+	SELECT hs.host_id, sc.cve
+	FROM software_cve sc
+	INNER JOIN host_software hs ON sc.software_id = hs.software_id
+
+	UNION
+
+	SELECT hos.host_id, osv.cve
+	FROM operating_system_vulnerabilities osv
+	INNER JOIN host_operating_system hos ON hos.os_id = osv.operating_system_id`
+
+func (ds *Datastore) UpdateHostCVEDetections(ctx context.Context, now time.Time) error {
+	// the first detection of the vulnerabilities already detected is kept
+	insertStmt := `
+		INSERT IGNORE INTO host_cve_detections (host_id, cve, first_detected_at)
+		SELECT host_id, cve, ? FROM (` + hostCVEsStmt + `) AS host_cves`
+	if _, err := ds.writer(ctx).ExecContext(ctx, insertStmt, now); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host cve detections")
+	}
+
+	// the vulnerabilities that are not detected anymore are remediated, if
+	// they are detected again later their SLA starts over.
+	const deleteStmt = `
+		DELETE d FROM host_cve_detections d
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM host_software hs
+			INNER JOIN software_cve sc ON sc.software_id = hs.software_id
+			WHERE hs.host_id = d.host_id AND sc.cve = d.cve
+		) AND NOT EXISTS (
+			SELECT 1
+			FROM host_operating_system hos
+			INNER JOIN operating_system_vulnerabilities osv ON osv.operating_system_id = hos.os_id
+			WHERE hos.host_id = d.host_id AND osv.cve = d.cve
+		)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, deleteStmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete remediated host cve detections")
+	}
+	return nil
+}
+
+// vulnerabilitySLABreachesWhere returns the conditions (and their arguments)
+// that select the overdue detections of the hosts of the team, with d the
+// alias of host_cve_detections, h of hosts and cm of cve_meta. ok is false if
+// no severity has an SLA.
+func vulnerabilitySLABreachesWhere(opts fleet.VulnerabilitySLABreachOptions) (where string, args []interface{}, ok bool) {
+	var breaches []string
+	// the severities are ordered from the most to the least severe, the CVSS
+	// scores of a severity are below the minimum score of the previous one.
+	var maxScore float64
+	for _, severity := range fleet.VulnerabilitySeverities {
+		minScore := severity.MinCVSSScore()
+		if days := opts.SLA.Days(severity); days > 0 {
+			cond := `(cm.cvss_score >= ? AND d.first_detected_at < ?`
+			args = append(args, minScore, opts.Now.AddDate(0, 0, -days))
+			if maxScore > 0 {
+				cond += ` AND cm.cvss_score < ?`
+				args = append(args, maxScore)
+			}
+			breaches = append(breaches, cond+`)`)
+		}
+		maxScore = minScore
+	}
+	if len(breaches) == 0 {
+		return "", nil, false
+	}
+
+	where = `(` + strings.Join(breaches, ` OR `) + `)`
+	if opts.TeamID != nil {
+		where += ` AND h.team_id = ?`
+		args = append(args, *opts.TeamID)
+	} else {
+		where += ` AND h.team_id IS NULL`
+	}
+	if opts.NotNotifiedOnly {
+		where += ` AND d.sla_breach_notified_at IS NULL`
+	}
+	return where, args, true
+}
+
+func (ds *Datastore) ListVulnerabilitySLABreaches(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) ([]*fleet.VulnerabilitySLABreach, error) {
+	breaches := []*fleet.VulnerabilitySLABreach{}
+	where, args, ok := vulnerabilitySLABreachesWhere(opts)
+	if !ok {
+		return breaches, nil
+	}
+
+	stmt := `
+		SELECT d.cve, cm.cvss_score, COUNT(*) AS hosts_count, MIN(d.first_detected_at) AS first_detected_at
+		FROM host_cve_detections d
+		INNER JOIN hosts h ON h.id = d.host_id
+		INNER JOIN cve_meta cm ON cm.cve = d.cve
+		WHERE ` + where + `
+		GROUP BY d.cve, cm.cvss_score
+		ORDER BY cm.cvss_score DESC, d.cve`
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &breaches, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select vulnerability sla breaches")
+	}
+	for _, b := range breaches {
+		b.Severity = fleet.VulnerabilitySeverityFromCVSSScore(b.CVSSScore)
+		b.SLADays = opts.SLA.Days(b.Severity)
+	}
+	return breaches, nil
+}
+
+func (ds *Datastore) MarkVulnerabilitySLABreachesNotified(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) error {
+	// the breaches already escalated keep their notification time
+	opts.NotNotifiedOnly = true
+	where, args, ok := vulnerabilitySLABreachesWhere(opts)
+	if !ok {
+		return nil
+	}
+
+	stmt := `
+		UPDATE host_cve_detections d
+		INNER JOIN hosts h ON h.id = d.host_id
+		INNER JOIN cve_meta cm ON cm.cve = d.cve
+		SET d.sla_breach_notified_at = ?
+		WHERE ` + where
+	args = append([]interface{}{opts.Now}, args...)
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "mark vulnerability sla breaches notified")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilitySLA(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	host3 := test.NewHost(t, ds, "host3", "", "host3key", "host3uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host3.ID}))
+
+	// software 1 has a critical and a medium vulnerability, software 2 a high
+	// one and a vulnerability without score
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		if _, err := q.ExecContext(ctx, `
+			INSERT INTO software (id, name, version, source, checksum) VALUES
+				(1, 'foo', '1.0', 'apps', UNHEX(MD5('foo'))),
+				(2, 'bar', '2.0', 'apps', UNHEX(MD5('bar')))`); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `
+			INSERT INTO software_cve (software_id, cve) VALUES
+				(1, 'CVE-2024-0001'), (1, 'CVE-2024-0002'), (2, 'CVE-2024-0003'), (2, 'CVE-2024-0004')`); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO cve_meta (cve, cvss_score) VALUES
+				('CVE-2024-0001', 9.8), ('CVE-2024-0002', 5.0), ('CVE-2024-0003', 7.5), ('CVE-2024-0004', NULL)`)
+		return err
+	})
+	setHostSoftware := func(hostID uint, softwareIDs ...uint) {
+		ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			if _, err := q.ExecContext(ctx, `DELETE FROM host_software WHERE host_id = ?`, hostID); err != nil {
+				return err
+			}
+			for _, id := range softwareIDs {
+				if _, err := q.ExecContext(ctx, `INSERT INTO host_software (host_id, software_id) VALUES (?, ?)`, hostID, id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	day0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	setHostSoftware(host1.ID, 1, 2)
+	setHostSoftware(host3.ID, 1)
+	require.NoError(t, ds.UpdateHostCVEDetections(ctx, day0))
+	// host2 gets the software later, the first detections are kept
+	setHostSoftware(host2.ID, 1)
+	require.NoError(t, ds.UpdateHostCVEDetections(ctx, day0.AddDate(0, 0, 10)))
+
+	sla := fleet.VulnerabilitySLASettings{Enable: true, CriticalDays: 14, HighDays: 30}
+	globalOpts := fleet.VulnerabilitySLABreachOptions{SLA: sla, Now: day0.AddDate(0, 0, 20)}
+	breaches, err := ds.ListVulnerabilitySLABreaches(ctx, globalOpts)
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	require.Equal(t, "CVE-2024-0001", breaches[0].CVE)
+	require.Equal(t, fleet.VulnerabilitySeverityCritical, breaches[0].Severity)
+	require.Equal(t, 14, breaches[0].SLADays)
+	require.EqualValues(t, 1, breaches[0].HostsCount)
+	require.Equal(t, day0, breaches[0].FirstDetectedAt.UTC())
+
+	// later, the high vulnerability and host2 are also overdue
+	globalOpts.Now = day0.AddDate(0, 0, 31)
+	breaches, err = ds.ListVulnerabilitySLABreaches(ctx, globalOpts)
+	require.NoError(t, err)
+	require.Len(t, breaches, 2)
+	require.Equal(t, "CVE-2024-0001", breaches[0].CVE)
+	require.EqualValues(t, 2, breaches[0].HostsCount)
+	require.Equal(t, "CVE-2024-0003", breaches[1].CVE)
+	require.Equal(t, fleet.VulnerabilitySeverityHigh, breaches[1].Severity)
+	require.EqualValues(t, 1, breaches[1].HostsCount)
+
+	// the team's hosts are listed separately
+	teamOpts := fleet.VulnerabilitySLABreachOptions{TeamID: &team.ID, SLA: fleet.VulnerabilitySLASettings{Enable: true, MediumDays: 7}, Now: globalOpts.Now}
+	breaches, err = ds.ListVulnerabilitySLABreaches(ctx, teamOpts)
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	require.Equal(t, "CVE-2024-0002", breaches[0].CVE)
+	require.Equal(t, fleet.VulnerabilitySeverityMedium, breaches[0].Severity)
+
+	// no SLA, nothing is overdue
+	breaches, err = ds.ListVulnerabilitySLABreaches(ctx, fleet.VulnerabilitySLABreachOptions{Now: globalOpts.Now})
+	require.NoError(t, err)
+	require.Empty(t, breaches)
+	require.NotNil(t, breaches)
+
+	// the breaches notified are not listed anymore for the notifications
+	notifyOpts := globalOpts
+	notifyOpts.Now = day0.AddDate(0, 0, 20)
+	require.NoError(t, ds.MarkVulnerabilitySLABreachesNotified(ctx, notifyOpts))
+	globalOpts.NotNotifiedOnly = true
+	breaches, err = ds.ListVulnerabilitySLABreaches(ctx, globalOpts)
+	require.NoError(t, err)
+	require.Len(t, breaches, 2)
+	require.Equal(t, "CVE-2024-0001", breaches[0].CVE)
+	require.EqualValues(t, 1, breaches[0].HostsCount) // host2
+	require.Equal(t, "CVE-2024-0003", breaches[1].CVE)
+
+	// the remediated vulnerabilities are not tracked anymore
+	setHostSoftware(host1.ID)
+	require.NoError(t, ds.UpdateHostCVEDetections(ctx, globalOpts.Now))
+	globalOpts.NotNotifiedOnly = false
+	breaches, err = ds.ListVulnerabilitySLABreaches(ctx, globalOpts)
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	require.Equal(t, "CVE-2024-0001", breaches[0].CVE)
+	require.EqualValues(t, 1, breaches[0].HostsCount)
+	require.Equal(t, day0.AddDate(0, 0, 10), breaches[0].FirstDetectedAt.UTC())
+}
@@ -3,6 +3,7 @@ package fleet
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 //go:generate go run gen_activity_doc.go "../../docs/Using Fleet/Audit-logs.md"
@@ -26,6 +27,9 @@ var ActivityDetailsList = []ActivityDetails{
 
 	ActivityTypeCreatedTeam{},
 	ActivityTypeDeletedTeam{},
+	ActivityTypeCreatedTenant{},
+	ActivityTypeEditedTenant{},
+	ActivityTypeDeletedTenant{},
 	ActivityTypeAppliedSpecTeam{},
 	ActivityTypeTransferredHostsToTeam{},
 
@@ -76,6 +80,8 @@ var ActivityDetailsList = []ActivityDetails{
 	ActivityTypeAddedScript{},
 	ActivityTypeDeletedScript{},
 	ActivityTypeEditedScript{},
+	ActivityTypeAddedScriptSchedule{},
+	ActivityTypeDeletedScriptSchedule{},
 
 	ActivityTypeCreatedWindowsProfile{},
 	ActivityTypeDeletedWindowsProfile{},
@@ -84,12 +90,84 @@ var ActivityDetailsList = []ActivityDetails{
 	ActivityTypeLockedHost{},
 	ActivityTypeUnlockedHost{},
 	ActivityTypeWipedHost{},
+	ActivityTypeReadHostUnlockPIN{},
+	ActivityTypeRequestedHostLockWipeApproval{},
+	ActivityTypeHostTamperDetected{},
+	ActivityTypeFleetdServiceRecovered{},
+	ActivityTypeEditedHostCustomFields{},
 
 	ActivityTypeCreatedDeclarationProfile{},
 	ActivityTypeDeletedDeclarationProfile{},
 	ActivityTypeEditedDeclarationProfile{},
 
 	ActivityTypeResentConfigurationProfile{},
+
+	ActivityTypeCreatedAPIToken{},
+	ActivityTypeDeletedAPIToken{},
+
+	ActivityTypeEnabledUserMFA{},
+	ActivityTypeDisabledUserMFA{},
+
+	ActivityTypeCreatedSecretVariable{},
+	ActivityTypeEditedSecretVariables{},
+	ActivityTypeDeletedSecretVariable{},
+	ActivityTypeRequestedHostLogCollection{},
+
+	ActivityTypeRequestedHostFileRetrieval{},
+	ActivityTypeDownloadedHostFile{},
+
+	ActivityTypeRequestedHostPprofCapture{},
+
+	ActivityTypeCreatedHostGroup{},
+	ActivityTypeDeletedHostGroup{},
+
+	ActivityTypeApprovedHostEnrollment{},
+	ActivityTypeMergedDuplicateHosts{},
+
+	ActivityTypeCreatedProfileRedeliveryCampaign{},
+	ActivityTypeEditedProfileRedeliveryCampaign{},
+	ActivityTypeUpdatedProfileRollout{},
+
+	ActivityTypeRolledBackConfig{},
+
+	ActivityTypeEditedEmailTemplate{},
+	ActivityTypeResetEmailTemplate{},
+
+	ActivityTypeAppliedTranslations{},
+	ActivityTypeDeletedTranslations{},
+
+	ActivityTypeEditedSharedLibraryItem{},
+	ActivityTypeEditedTeamSharedLibraryOptOuts{},
+
+	ActivityTypeIsolatedHost{},
+	ActivityTypeUnisolatedHost{},
+
+	ActivityTypeRanHostRemoteQuery{},
+
+	ActivityTypeRestartedHost{},
+	ActivityTypeShutDownHost{},
+
+	ActivityTypeExportedMDMAssets{},
+	ActivityTypeImportedMDMAssets{},
+
+	ActivityTypeEnabledLostMode{},
+	ActivityTypeDisabledLostMode{},
+
+	ActivityTypeReadHostActivationLockBypassCode{},
+	ActivityTypeClearedHostActivationLockBypassCode{},
+
+	ActivityTypeRevokedHostEnrollmentCertificates{},
+
+	ActivityTypeReadHostRecoveryLockPassword{},
+
+	ActivityTypeSetHostFirmwarePassword{},
+	ActivityTypeReadHostFirmwarePassword{},
+
+	ActivityTypeUnlockedHostUserAccount{},
+
+	ActivityTypeRepairedHostMDM{},
+
+	ActivityTypeMDMUserChannelEstablished{},
 }
 
 type ActivityDetails interface {
@@ -400,6 +478,63 @@ func (a ActivityTypeDeletedTeam) Documentation() (activity string, details strin
 }`
 }
 
+type ActivityTypeCreatedTenant struct {
+	ID   uint   `json:"tenant_id"`
+	Name string `json:"tenant_name"`
+}
+
+func (a ActivityTypeCreatedTenant) ActivityName() string {
+	return "created_tenant"
+}
+
+func (a ActivityTypeCreatedTenant) Documentation() (activity string, details string, detailsExample string) {
+	return `Generated when creating tenants.`,
+		`This activity contains the following fields:
+- "tenant_id": unique ID of the created tenant.
+- "tenant_name": the name of the created tenant.`, `{
+	"tenant_id": 12,
+	"tenant_name": "Acme"
+}`
+}
+
+type ActivityTypeEditedTenant struct {
+	ID   uint   `json:"tenant_id"`
+	Name string `json:"tenant_name"`
+}
+
+func (a ActivityTypeEditedTenant) ActivityName() string {
+	return "edited_tenant"
+}
+
+func (a ActivityTypeEditedTenant) Documentation() (activity string, details string, detailsExample string) {
+	return `Generated when modifying a tenant, its teams, its users or its APNs certificate.`,
+		`This activity contains the following fields:
+- "tenant_id": unique ID of the modified tenant.
+- "tenant_name": the name of the modified tenant.`, `{
+	"tenant_id": 12,
+	"tenant_name": "Acme"
+}`
+}
+
+type ActivityTypeDeletedTenant struct {
+	ID   uint   `json:"tenant_id"`
+	Name string `json:"tenant_name"`
+}
+
+func (a ActivityTypeDeletedTenant) ActivityName() string {
+	return "deleted_tenant"
+}
+
+func (a ActivityTypeDeletedTenant) Documentation() (activity string, details string, detailsExample string) {
+	return `Generated when deleting tenants.`,
+		`This activity contains the following fields:
+- "tenant_id": unique ID of the deleted tenant.
+- "tenant_name": the name of the deleted tenant.`, `{
+	"tenant_id": 12,
+	"tenant_name": "Acme"
+}`
+}
+
 type TeamActivityDetail struct {
 	ID   uint   `json:"id"`
 	Name string `json:"name"`
@@ -1192,6 +1327,56 @@ func (a ActivityTypeEditedScript) Documentation() (activity, details, detailsExa
 }`
 }
 
+type ActivityTypeAddedScriptSchedule struct {
+	ScriptName string  `json:"script_name"`
+	LocalTime  string  `json:"local_time"`
+	TeamID     *uint   `json:"team_id"`
+	TeamName   *string `json:"team_name"`
+}
+
+func (a ActivityTypeAddedScriptSchedule) ActivityName() string {
+	return "added_script_schedule"
+}
+
+func (a ActivityTypeAddedScriptSchedule) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a script is scheduled to run every day at a local time of the hosts.`,
+		`This activity contains the following fields:
+- "script_name": Name of the script.
+- "local_time": The time of day (HH:MM) at which the script runs, in the local timezone of each host.
+- "team_id": The ID of the team that the script applies to, ` + "`null`" + ` if it applies to devices that are not in a team.
+- "team_name": The name of the team that the script applies to, ` + "`null`" + ` if it applies to devices that are not in a team.`, `{
+  "script_name": "set-timezones.sh",
+  "local_time": "02:00",
+  "team_id": 123,
+  "team_name": "Workstations"
+}`
+}
+
+type ActivityTypeDeletedScriptSchedule struct {
+	ScriptName string  `json:"script_name"`
+	LocalTime  string  `json:"local_time"`
+	TeamID     *uint   `json:"team_id"`
+	TeamName   *string `json:"team_name"`
+}
+
+func (a ActivityTypeDeletedScriptSchedule) ActivityName() string {
+	return "deleted_script_schedule"
+}
+
+func (a ActivityTypeDeletedScriptSchedule) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the schedule of a script is deleted.`,
+		`This activity contains the following fields:
+- "script_name": Name of the script.
+- "local_time": The time of day (HH:MM) at which the script was scheduled to run, in the local timezone of each host.
+- "team_id": The ID of the team that the script applies to, ` + "`null`" + ` if it applies to devices that are not in a team.
+- "team_name": The name of the team that the script applies to, ` + "`null`" + ` if it applies to devices that are not in a team.`, `{
+  "script_name": "set-timezones.sh",
+  "local_time": "02:00",
+  "team_id": 123,
+  "team_name": "Workstations"
+}`
+}
+
 type ActivityTypeCreatedWindowsProfile struct {
 	ProfileName string  `json:"profile_name"`
 	TeamID      *uint   `json:"team_id"`
@@ -1323,6 +1508,150 @@ func (a ActivityTypeWipedHost) Documentation() (activity, details, detailsExampl
 }`
 }
 
+type ActivityTypeReadHostUnlockPIN struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeReadHostUnlockPIN) ActivityName() string {
+	return "read_host_unlock_pin"
+}
+
+func (a ActivityTypeReadHostUnlockPIN) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeReadHostUnlockPIN) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user reads the unlock PIN of a locked (or pending lock) macOS host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeRequestedHostLockWipeApproval struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	Action          string `json:"action"`
+}
+
+func (a ActivityTypeRequestedHostLockWipeApproval) ActivityName() string {
+	return "requested_host_lock_wipe_approval"
+}
+
+func (a ActivityTypeRequestedHostLockWipeApproval) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRequestedHostLockWipeApproval) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user requests to lock or wipe a host that requires the approval of a second user.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "action": The requested action, either "lock" or "wipe".`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "action": "wipe"
+}`
+}
+
+type ActivityTypeHostTamperDetected struct {
+	HostID          uint                `json:"host_id"`
+	HostDisplayName string              `json:"host_display_name"`
+	EventType       HostTamperEventType `json:"event_type"`
+	Details         string              `json:"details"`
+	DetectedAt      time.Time           `json:"detected_at"`
+}
+
+func (a ActivityTypeHostTamperDetected) ActivityName() string {
+	return "host_tamper_detected"
+}
+
+func (a ActivityTypeHostTamperDetected) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeHostTamperDetected) Documentation() (activity, details, detailsExample string) {
+	return `Generated when fleetd detects a tamper event on a host. This activity is not generated by a user.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "event_type": The type of tamper event, one of "osqueryd_killed", "service_disabled" or "config_modified".
+- "details": Description of the event, e.g. the path of the modified file.
+- "detected_at": Time at which fleetd detected the event.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "event_type": "config_modified",
+  "details": "/Library/LaunchDaemons/com.fleetdm.orbit.plist was modified",
+  "detected_at": "2024-05-13T10:00:00Z"
+}`
+}
+
+type ActivityTypeFleetdServiceRecovered struct {
+	HostID          uint                      `json:"host_id"`
+	HostDisplayName string                    `json:"host_display_name"`
+	Reason          HostServiceRecoveryReason `json:"reason"`
+	Details         string                    `json:"details"`
+	RecoveredAt     time.Time                 `json:"recovered_at"`
+}
+
+func (a ActivityTypeFleetdServiceRecovered) ActivityName() string {
+	return "fleetd_service_recovered"
+}
+
+func (a ActivityTypeFleetdServiceRecovered) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeFleetdServiceRecovered) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the fleetd service of a Windows host was restarted by its recovery actions. This activity is not generated by a user.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "reason": Why the service was recovered, either "crash" (fleetd stopped unexpectedly) or "watchdog" (fleetd stopped responding and was stopped by its watchdog).
+- "details": Description of the failure, e.g. the time of the last heartbeat.
+- "recovered_at": Time at which fleetd started again.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's Dell XPS",
+  "reason": "watchdog",
+  "details": "no heartbeat since 2024-05-13T09:55:00Z",
+  "recovered_at": "2024-05-13T10:00:00Z"
+}`
+}
+
+type ActivityTypeEditedHostCustomFields struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	// NotesEdited is true if the notes of the host were edited.
+	NotesEdited bool `json:"notes_edited"`
+	// CustomFields are the names of the edited custom fields.
+	CustomFields []string `json:"custom_fields"`
+}
+
+func (a ActivityTypeEditedHostCustomFields) ActivityName() string {
+	return "edited_host_custom_fields"
+}
+
+func (a ActivityTypeEditedHostCustomFields) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeEditedHostCustomFields) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user edits the notes or custom fields of a host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "notes_edited": Whether the notes of the host were edited.
+- "custom_fields": Names of the edited custom fields.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "notes_edited": true,
+  "custom_fields": ["owner", "asset_tag"]
+}`
+}
+
 type ActivityTypeCreatedDeclarationProfile struct {
 	ProfileName string  `json:"profile_name"`
 	Identifier  string  `json:"identifier"`
@@ -1492,3 +1821,1011 @@ func LogRoleChangeActivities(ctx context.Context, ds Datastore, adminUser *User,
 	}
 	return nil
 }
+
+type ActivityTypeCreatedAPIToken struct {
+	TokenName string        `json:"token_name"`
+	Scope     APITokenScope `json:"scope"`
+	TeamID    uint          `json:"team_id"`
+	TeamName  string        `json:"team_name"`
+}
+
+func (a ActivityTypeCreatedAPIToken) ActivityName() string {
+	return "created_api_token"
+}
+
+func (a ActivityTypeCreatedAPIToken) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user creates a team-scoped API token.`,
+		`This activity contains the following fields:
+- "token_name": Name of the API token.
+- "scope": Scope of the API token (inventory_read, scripts or mdm).
+- "team_id": The ID of the team the API token is bound to.
+- "team_name": The name of the team the API token is bound to.`, `{
+  "token_name": "Inventory sync",
+  "scope": "inventory_read",
+  "team_id": 123,
+  "team_name": "Workstations"
+}`
+}
+
+type ActivityTypeDeletedAPIToken struct {
+	TokenName string        `json:"token_name"`
+	Scope     APITokenScope `json:"scope"`
+	TeamID    uint          `json:"team_id"`
+	TeamName  string        `json:"team_name"`
+}
+
+func (a ActivityTypeDeletedAPIToken) ActivityName() string {
+	return "deleted_api_token"
+}
+
+func (a ActivityTypeDeletedAPIToken) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user revokes a team-scoped API token.`,
+		`This activity contains the following fields:
+- "token_name": Name of the API token.
+- "scope": Scope of the API token (inventory_read, scripts or mdm).
+- "team_id": The ID of the team the API token was bound to.
+- "team_name": The name of the team the API token was bound to.`, `{
+  "token_name": "Inventory sync",
+  "scope": "inventory_read",
+  "team_id": 123,
+  "team_name": "Workstations"
+}`
+}
+
+type ActivityTypeEnabledUserMFA struct {
+	UserID    uint      `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	UserEmail string    `json:"user_email"`
+	Method    MFAMethod `json:"method"`
+}
+
+func (a ActivityTypeEnabledUserMFA) ActivityName() string {
+	return "enabled_user_mfa"
+}
+
+func (a ActivityTypeEnabledUserMFA) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user adds a second authentication factor to their account.`,
+		`This activity contains the following fields:
+- "user_id": Unique ID of the user in Fleet.
+- "user_name": Name of the user.
+- "user_email": E-mail of the user.
+- "method": The second factor that was added (totp or webauthn).`, `{
+  "user_id": 43,
+  "user_name": "Foo",
+  "user_email": "foo@example.com",
+  "method": "totp"
+}`
+}
+
+type ActivityTypeDisabledUserMFA struct {
+	UserID    uint   `json:"user_id"`
+	UserName  string `json:"user_name"`
+	UserEmail string `json:"user_email"`
+	// Method is the second factor that was removed, empty if all factors were
+	// reset by an admin.
+	Method MFAMethod `json:"method"`
+}
+
+func (a ActivityTypeDisabledUserMFA) ActivityName() string {
+	return "disabled_user_mfa"
+}
+
+func (a ActivityTypeDisabledUserMFA) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a second authentication factor is removed from a user account, or when an admin resets all of them.`,
+		`This activity contains the following fields:
+- "user_id": Unique ID of the user in Fleet.
+- "user_name": Name of the user.
+- "user_email": E-mail of the user.
+- "method": The second factor that was removed (totp or webauthn), empty if all factors were reset by an admin.`, `{
+  "user_id": 43,
+  "user_name": "Foo",
+  "user_email": "foo@example.com",
+  "method": "webauthn"
+}`
+}
+
+type ActivityTypeCreatedSecretVariable struct {
+	SecretName string `json:"secret_name"`
+}
+
+func (a ActivityTypeCreatedSecretVariable) ActivityName() string {
+	return "created_secret_variable"
+}
+
+func (a ActivityTypeCreatedSecretVariable) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a secret variable is created. The value of the secret is never included.`,
+		`This activity contains the following fields:
+- "secret_name": Name of the secret variable, without the FLEET_SECRET_ prefix.`, `{
+  "secret_name": "API_KEY"
+}`
+}
+
+type ActivityTypeEditedSecretVariables struct {
+	SecretNames []string `json:"secret_names"`
+}
+
+func (a ActivityTypeEditedSecretVariables) ActivityName() string {
+	return "edited_secret_variables"
+}
+
+func (a ActivityTypeEditedSecretVariables) Documentation() (activity, details, detailsExample string) {
+	return `Generated when secret variables are created or updated in bulk, e.g. by fleetctl gitops. The values of the secrets are never included.`,
+		`This activity contains the following fields:
+- "secret_names": Names of the secret variables that were created or updated, without the FLEET_SECRET_ prefix.`, `{
+  "secret_names": ["API_KEY", "SERVER_URL"]
+}`
+}
+
+type ActivityTypeDeletedSecretVariable struct {
+	SecretName string `json:"secret_name"`
+}
+
+func (a ActivityTypeDeletedSecretVariable) ActivityName() string {
+	return "deleted_secret_variable"
+}
+
+func (a ActivityTypeDeletedSecretVariable) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a secret variable is deleted.`,
+		`This activity contains the following fields:
+- "secret_name": Name of the secret variable, without the FLEET_SECRET_ prefix.`, `{
+  "secret_name": "API_KEY"
+}`
+}
+
+type ActivityTypeRequestedHostLogCollection struct {
+	HostID          uint                  `json:"host_id"`
+	HostDisplayName string                `json:"host_display_name"`
+	ExecutionID     string                `json:"execution_id"`
+	LogType         HostLogCollectionType `json:"log_type"`
+	StartTime       time.Time             `json:"start_time"`
+	EndTime         time.Time             `json:"end_time"`
+}
+
+func (a ActivityTypeRequestedHostLogCollection) ActivityName() string {
+	return "requested_host_log_collection"
+}
+
+func (a ActivityTypeRequestedHostLogCollection) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRequestedHostLogCollection) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user requests the collection of logs from a host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "execution_id": Execution ID of the log collection request.
+- "log_type": Type of logs collected, either "windows_event_log" or "macos_unified_log".
+- "start_time": Start of the time range of the collected logs.
+- "end_time": End of the time range of the collected logs.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "execution_id": "d6cffa75-b5b5-41ef-9230-15073c8a88cf",
+  "log_type": "macos_unified_log",
+  "start_time": "2024-04-22T10:00:00Z",
+  "end_time": "2024-04-22T12:00:00Z"
+}`
+}
+
+type ActivityTypeRequestedHostFileRetrieval struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	ExecutionID     string `json:"execution_id"`
+	Path            string `json:"path"`
+}
+
+func (a ActivityTypeRequestedHostFileRetrieval) ActivityName() string {
+	return "requested_host_file_retrieval"
+}
+
+func (a ActivityTypeRequestedHostFileRetrieval) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRequestedHostFileRetrieval) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user requests the retrieval of a file from a host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "execution_id": Execution ID of the file retrieval request.
+- "path": Path of the requested file on the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "execution_id": "d6cffa75-b5b5-41ef-9230-15073c8a88cf",
+  "path": "/var/log/install.log"
+}`
+}
+
+type ActivityTypeDownloadedHostFile struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	ExecutionID     string `json:"execution_id"`
+	Path            string `json:"path"`
+}
+
+func (a ActivityTypeDownloadedHostFile) ActivityName() string {
+	return "downloaded_host_file"
+}
+
+func (a ActivityTypeDownloadedHostFile) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeDownloadedHostFile) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user downloads a file retrieved from a host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "execution_id": Execution ID of the file retrieval request.
+- "path": Path of the file on the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "execution_id": "d6cffa75-b5b5-41ef-9230-15073c8a88cf",
+  "path": "/var/log/install.log"
+}`
+}
+
+type ActivityTypeRequestedHostPprofCapture struct {
+	HostID          uint                 `json:"host_id"`
+	HostDisplayName string               `json:"host_display_name"`
+	ExecutionID     string               `json:"execution_id"`
+	ProfileType     HostPprofProfileType `json:"profile_type"`
+	DurationSeconds uint                 `json:"duration_seconds"`
+}
+
+func (a ActivityTypeRequestedHostPprofCapture) ActivityName() string {
+	return "requested_host_pprof_capture"
+}
+
+func (a ActivityTypeRequestedHostPprofCapture) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRequestedHostPprofCapture) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user requests the capture of a performance profile of the fleetd agent running on a host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "execution_id": Execution ID of the pprof capture request.
+- "profile_type": Type of profile, either "cpu", "heap" or "goroutine".
+- "duration_seconds": Duration of the capture in seconds, 0 for heap and goroutine profiles.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "execution_id": "d6cffa75-b5b5-41ef-9230-15073c8a88cf",
+  "profile_type": "cpu",
+  "duration_seconds": 30
+}`
+}
+
+type ActivityTypeCreatedHostGroup struct {
+	ID   uint   `json:"host_group_id"`
+	Name string `json:"host_group_name"`
+}
+
+func (a ActivityTypeCreatedHostGroup) ActivityName() string {
+	return "created_host_group"
+}
+
+func (a ActivityTypeCreatedHostGroup) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a host group is created.`,
+		`This activity contains the following fields:
+- "host_group_id": ID of the host group.
+- "host_group_name": Name of the host group.`, `{
+  "host_group_id": 123,
+  "host_group_name": "Incident 42 hosts"
+}`
+}
+
+type ActivityTypeDeletedHostGroup struct {
+	ID   uint   `json:"host_group_id"`
+	Name string `json:"host_group_name"`
+}
+
+func (a ActivityTypeDeletedHostGroup) ActivityName() string {
+	return "deleted_host_group"
+}
+
+func (a ActivityTypeDeletedHostGroup) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a host group is deleted.`,
+		`This activity contains the following fields:
+- "host_group_id": ID of the host group.
+- "host_group_name": Name of the host group.`, `{
+  "host_group_id": 123,
+  "host_group_name": "Incident 42 hosts"
+}`
+}
+
+type ActivityTypeApprovedHostEnrollment struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	HostSerial      string `json:"host_serial"`
+}
+
+func (a ActivityTypeApprovedHostEnrollment) ActivityName() string {
+	return "approved_host_enrollment"
+}
+
+func (a ActivityTypeApprovedHostEnrollment) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeApprovedHostEnrollment) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user approves the enrollment of a host that was pending approval.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "host_serial": Hardware serial number of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "host_serial": "C02XL0GHJGH5"
+}`
+}
+
+type ActivityTypeMergedDuplicateHosts struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	MergedHostIDs   []uint `json:"merged_host_ids"`
+}
+
+func (a ActivityTypeMergedDuplicateHosts) ActivityName() string {
+	return "merged_duplicate_hosts"
+}
+
+func (a ActivityTypeMergedDuplicateHosts) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeMergedDuplicateHosts) Documentation() (activity, details, detailsExample string) {
+	return `Generated when duplicate hosts are merged into a host, either by a user or automatically by Fleet.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host that was kept.
+- "host_display_name": Display name of the host that was kept.
+- "merged_host_ids": IDs of the duplicate hosts that were merged and deleted.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "merged_host_ids": [2, 3]
+}`
+}
+
+type ActivityTypeCreatedProfileRedeliveryCampaign struct {
+	CampaignID     uint    `json:"campaign_id"`
+	ProfileName    string  `json:"profile_name"`
+	TeamID         *uint   `json:"team_id"`
+	TeamName       *string `json:"team_name"`
+	HostsPerMinute uint    `json:"hosts_per_minute"`
+	TotalHosts     uint    `json:"total_hosts"`
+}
+
+func (a ActivityTypeCreatedProfileRedeliveryCampaign) ActivityName() string {
+	return "created_profile_redelivery_campaign"
+}
+
+func (a ActivityTypeCreatedProfileRedeliveryCampaign) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user creates a campaign to redeliver a configuration profile to its hosts at a limited rate.`,
+		`This activity contains the following fields:
+- "campaign_id": ID of the campaign.
+- "profile_name": Name of the configuration profile.
+- "team_id": The ID of the team of the profile, null for "No team".
+- "team_name": The name of the team of the profile, null for "No team".
+- "hosts_per_minute": Number of hosts that get the profile redelivered per minute.
+- "total_hosts": Number of hosts targeted by the campaign.`, `{
+  "campaign_id": 1,
+  "profile_name": "Wi-Fi",
+  "team_id": 123,
+  "team_name": "Workstations",
+  "hosts_per_minute": 500,
+  "total_hosts": 30000
+}`
+}
+
+type ActivityTypeEditedProfileRedeliveryCampaign struct {
+	CampaignID     uint                               `json:"campaign_id"`
+	ProfileName    string                             `json:"profile_name"`
+	TeamID         *uint                              `json:"team_id"`
+	TeamName       *string                            `json:"team_name"`
+	Status         MDMProfileRedeliveryCampaignStatus `json:"status"`
+	HostsPerMinute uint                               `json:"hosts_per_minute"`
+}
+
+func (a ActivityTypeEditedProfileRedeliveryCampaign) ActivityName() string {
+	return "edited_profile_redelivery_campaign"
+}
+
+func (a ActivityTypeEditedProfileRedeliveryCampaign) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user pauses or resumes a profile redelivery campaign, or changes its rate.`,
+		`This activity contains the following fields:
+- "campaign_id": ID of the campaign.
+- "profile_name": Name of the configuration profile.
+- "team_id": The ID of the team of the profile, null for "No team".
+- "team_name": The name of the team of the profile, null for "No team".
+- "status": Status of the campaign after the change, "running" or "paused".
+- "hosts_per_minute": Number of hosts that get the profile redelivered per minute after the change.`, `{
+  "campaign_id": 1,
+  "profile_name": "Wi-Fi",
+  "team_id": 123,
+  "team_name": "Workstations",
+  "status": "paused",
+  "hosts_per_minute": 500
+}`
+}
+
+type ActivityTypeUpdatedProfileRollout struct {
+	ProfileUUID string                  `json:"profile_uuid"`
+	ProfileName string                  `json:"profile_name"`
+	TeamID      *uint                   `json:"team_id"`
+	TeamName    *string                 `json:"team_name"`
+	Status      MDMProfileRolloutStatus `json:"status"`
+	Reason      string                  `json:"reason"`
+}
+
+func (a ActivityTypeUpdatedProfileRollout) ActivityName() string {
+	return "updated_profile_rollout"
+}
+
+func (a ActivityTypeUpdatedProfileRollout) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the staged rollout of a configuration profile is automatically continued to all its targeted hosts or halted based on the results of its canary hosts, or when a user continues, halts or restarts it.`,
+		`This activity contains the following fields:
+- "profile_uuid": UUID of the configuration profile.
+- "profile_name": Name of the configuration profile.
+- "team_id": The ID of the team of the profile, null for "No team".
+- "team_name": The name of the team of the profile, null for "No team".
+- "status": Status of the rollout after the change, "canary", "completed" or "halted".
+- "reason": Why the status of the rollout changed.`, `{
+  "profile_uuid": "a1b2c3d4-e5f6-7890-abcd-ef1234567890",
+  "profile_name": "Wi-Fi",
+  "team_id": 123,
+  "team_name": "Workstations",
+  "status": "halted",
+  "reason": "The profile failed on 3 of 10 canary hosts, above the 20% failure threshold."
+}`
+}
+
+type ActivityTypeRolledBackConfig struct {
+	VersionID uint    `json:"version_id"`
+	TeamID    *uint   `json:"team_id"`
+	TeamName  *string `json:"team_name"`
+}
+
+func (a ActivityTypeRolledBackConfig) ActivityName() string {
+	return "rolled_back_config"
+}
+
+func (a ActivityTypeRolledBackConfig) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the global or a team's configuration, along with its configuration profiles, is rolled back to a previous version.`,
+		`This activity contains the following fields:
+- "version_id": ID of the configuration version that was restored.
+- "team_id": The ID of the team whose configuration was rolled back, null for the global configuration.
+- "team_name": The name of the team whose configuration was rolled back, null for the global configuration.`, `{
+  "version_id": 42,
+  "team_id": 123,
+  "team_name": "Workstations"
+}`
+}
+
+type ActivityTypeEditedEmailTemplate struct {
+	Name EmailTemplateName `json:"name"`
+}
+
+func (a ActivityTypeEditedEmailTemplate) ActivityName() string {
+	return "edited_email_template"
+}
+
+func (a ActivityTypeEditedEmailTemplate) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the template or sender of an email sent by Fleet is customized.`,
+		`This activity contains the following fields:
+- "name": The name of the email template ("invite", "password_reset", "mdm_migration" or "lock_wipe_notification").`, `{
+  "name": "invite"
+}`
+}
+
+type ActivityTypeResetEmailTemplate struct {
+	Name EmailTemplateName `json:"name"`
+}
+
+func (a ActivityTypeResetEmailTemplate) ActivityName() string {
+	return "reset_email_template"
+}
+
+func (a ActivityTypeResetEmailTemplate) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the customization of an email sent by Fleet is removed, so that the default template is used.`,
+		`This activity contains the following fields:
+- "name": The name of the email template ("invite", "password_reset", "mdm_migration" or "lock_wipe_notification").`, `{
+  "name": "password_reset"
+}`
+}
+
+type ActivityTypeAppliedTranslations struct {
+	Locale string `json:"locale"`
+}
+
+func (a ActivityTypeAppliedTranslations) ActivityName() string {
+	return "applied_translations"
+}
+
+func (a ActivityTypeAppliedTranslations) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the translations of the end-user facing messages for a locale are created or replaced.`,
+		`This activity contains the following fields:
+- "locale": The locale of the translations.`, `{
+  "locale": "fr"
+}`
+}
+
+type ActivityTypeDeletedTranslations struct {
+	Locale string `json:"locale"`
+}
+
+func (a ActivityTypeDeletedTranslations) ActivityName() string {
+	return "deleted_translations"
+}
+
+func (a ActivityTypeDeletedTranslations) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the translations of the end-user facing messages for a locale are deleted.`,
+		`This activity contains the following fields:
+- "locale": The locale of the translations.`, `{
+  "locale": "fr"
+}`
+}
+
+type ActivityTypeEditedSharedLibraryItem struct {
+	ScriptID           *uint   `json:"script_id"`
+	ProfileUUID        *string `json:"profile_uuid"`
+	Name               string  `json:"name"`
+	SharedWithAllTeams bool    `json:"shared_with_all_teams"`
+}
+
+func (a ActivityTypeEditedSharedLibraryItem) ActivityName() string {
+	return "edited_shared_library_item"
+}
+
+func (a ActivityTypeEditedSharedLibraryItem) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a script or configuration profile of "No team" is shared with all teams, or is no longer shared.`,
+		`This activity contains the following fields:
+- "script_id": The ID of the script, null if a configuration profile was edited.
+- "profile_uuid": The UUID of the configuration profile, null if a script was edited.
+- "name": The name of the script or configuration profile.
+- "shared_with_all_teams": Whether the script or configuration profile is now shared with all teams.`, `{
+  "script_id": null,
+  "profile_uuid": "a1b2c3d4-e5f6-7890-abcd-ef1234567890",
+  "name": "Wi-Fi",
+  "shared_with_all_teams": true
+}`
+}
+
+type ActivityTypeEditedTeamSharedLibraryOptOuts struct {
+	TeamID       uint     `json:"team_id"`
+	TeamName     string   `json:"team_name"`
+	ScriptIDs    []uint   `json:"script_ids"`
+	ProfileUUIDs []string `json:"profile_uuids"`
+}
+
+func (a ActivityTypeEditedTeamSharedLibraryOptOuts) ActivityName() string {
+	return "edited_team_shared_library_opt_outs"
+}
+
+func (a ActivityTypeEditedTeamSharedLibraryOptOuts) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the scripts and configuration profiles shared with all teams that a team opted out of are edited.`,
+		`This activity contains the following fields:
+- "team_id": The ID of the team.
+- "team_name": The name of the team.
+- "script_ids": The IDs of the shared scripts that the team opted out of.
+- "profile_uuids": The UUIDs of the shared configuration profiles that the team opted out of.`, `{
+  "team_id": 123,
+  "team_name": "Workstations",
+  "script_ids": [4, 5],
+  "profile_uuids": ["a1b2c3d4-e5f6-7890-abcd-ef1234567890"]
+}`
+}
+
+type ActivityTypeIsolatedHost struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeIsolatedHost) ActivityName() string {
+	return "isolated_host"
+}
+
+func (a ActivityTypeIsolatedHost) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeIsolatedHost) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to isolate a host from the network.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeUnisolatedHost struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeUnisolatedHost) ActivityName() string {
+	return "unisolated_host"
+}
+
+func (a ActivityTypeUnisolatedHost) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeUnisolatedHost) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to release a host from its network isolation.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeRanHostRemoteQuery struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	ExecutionID     string `json:"execution_id"`
+	Query           string `json:"query"`
+}
+
+func (a ActivityTypeRanHostRemoteQuery) ActivityName() string {
+	return "ran_host_remote_query"
+}
+
+func (a ActivityTypeRanHostRemoteQuery) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRanHostRemoteQuery) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user requests a remote query to be run on a host by the fleetd agent.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "execution_id": Execution ID of the remote query request.
+- "query": The SQL statement run on the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "execution_id": "d6cffa75-b5b5-41ef-9230-15073c8a88cf",
+  "query": "SELECT * FROM processes WHERE name = 'nc'"
+}`
+}
+
+type ActivityTypeRestartedHost struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeRestartedHost) ActivityName() string {
+	return "restarted_host"
+}
+
+func (a ActivityTypeRestartedHost) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRestartedHost) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to restart a host via MDM.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeShutDownHost struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeShutDownHost) ActivityName() string {
+	return "shut_down_host"
+}
+
+func (a ActivityTypeShutDownHost) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeShutDownHost) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to shut down a host via MDM.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeExportedMDMAssets struct {
+	AssetCount            int `json:"asset_count"`
+	BootstrapPackageCount int `json:"bootstrap_package_count"`
+}
+
+func (a ActivityTypeExportedMDMAssets) ActivityName() string {
+	return "exported_mdm_assets"
+}
+
+func (a ActivityTypeExportedMDMAssets) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user exports the MDM assets (certificates, keys, tokens and bootstrap packages) of the Fleet server as an encrypted bundle.`,
+		`This activity contains the following fields:
+- "asset_count": Number of certificates, keys and tokens in the bundle.
+- "bootstrap_package_count": Number of bootstrap packages in the bundle.`, `{
+  "asset_count": 7,
+  "bootstrap_package_count": 2
+}`
+}
+
+type ActivityTypeImportedMDMAssets struct {
+	RestoredCount int `json:"restored_count"`
+	SkippedCount  int `json:"skipped_count"`
+}
+
+func (a ActivityTypeImportedMDMAssets) ActivityName() string {
+	return "imported_mdm_assets"
+}
+
+func (a ActivityTypeImportedMDMAssets) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user imports a bundle of MDM assets exported by a Fleet server.`,
+		`This activity contains the following fields:
+- "restored_count": Number of assets and bootstrap packages restored.
+- "skipped_count": Number of assets and bootstrap packages skipped, because they are set in the server configuration, already exist, or their tenant or team does not exist.`, `{
+  "restored_count": 3,
+  "skipped_count": 4
+}`
+}
+
+type ActivityTypeEnabledLostMode struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeEnabledLostMode) ActivityName() string {
+	return "enabled_lost_mode"
+}
+
+func (a ActivityTypeEnabledLostMode) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeEnabledLostMode) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to put an iOS or iPadOS host in Lost Mode.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's iPhone"
+}`
+}
+
+type ActivityTypeDisabledLostMode struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeDisabledLostMode) ActivityName() string {
+	return "disabled_lost_mode"
+}
+
+func (a ActivityTypeDisabledLostMode) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeDisabledLostMode) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to take an iOS or iPadOS host out of Lost Mode.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's iPhone"
+}`
+}
+
+type ActivityTypeReadHostActivationLockBypassCode struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeReadHostActivationLockBypassCode) ActivityName() string {
+	return "read_host_activation_lock_bypass_code"
+}
+
+func (a ActivityTypeReadHostActivationLockBypassCode) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeReadHostActivationLockBypassCode) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user reads the Activation Lock bypass code escrowed by an Apple host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's iPhone"
+}`
+}
+
+type ActivityTypeClearedHostActivationLockBypassCode struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeClearedHostActivationLockBypassCode) ActivityName() string {
+	return "cleared_host_activation_lock_bypass_code"
+}
+
+func (a ActivityTypeClearedHostActivationLockBypassCode) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeClearedHostActivationLockBypassCode) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to clear the Activation Lock bypass code of an Apple host.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's iPhone"
+}`
+}
+
+type ActivityTypeRevokedHostEnrollmentCertificates struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	Count           int    `json:"count"`
+}
+
+func (a ActivityTypeRevokedHostEnrollmentCertificates) ActivityName() string {
+	return "revoked_host_enrollment_certificates"
+}
+
+func (a ActivityTypeRevokedHostEnrollmentCertificates) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRevokedHostEnrollmentCertificates) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user revokes the client certificates a host used to enroll, which forces the host to enroll again.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "count": Number of certificates revoked.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "count": 1
+}`
+}
+
+type ActivityTypeReadHostRecoveryLockPassword struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeReadHostRecoveryLockPassword) ActivityName() string {
+	return "read_host_recovery_lock_password"
+}
+
+func (a ActivityTypeReadHostRecoveryLockPassword) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeReadHostRecoveryLockPassword) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user reads the Recovery Lock password of an Apple silicon Mac.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeSetHostFirmwarePassword struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeSetHostFirmwarePassword) ActivityName() string {
+	return "set_host_firmware_password"
+}
+
+func (a ActivityTypeSetHostFirmwarePassword) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeSetHostFirmwarePassword) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sets a new firmware password on an Intel Mac.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeReadHostFirmwarePassword struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeReadHostFirmwarePassword) ActivityName() string {
+	return "read_host_firmware_password"
+}
+
+func (a ActivityTypeReadHostFirmwarePassword) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeReadHostFirmwarePassword) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user reads the firmware password of an Intel Mac.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeUnlockedHostUserAccount struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+	Username        string `json:"username"`
+}
+
+func (a ActivityTypeUnlockedHostUserAccount) ActivityName() string {
+	return "unlocked_host_user_account"
+}
+
+func (a ActivityTypeUnlockedHostUserAccount) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeUnlockedHostUserAccount) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user sends a request to unlock a local user account of a macOS host via MDM.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.
+- "username": The name of the unlocked user account.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro",
+  "username": "anna"
+}`
+}
+
+type ActivityTypeRepairedHostMDM struct {
+	HostID          uint   `json:"host_id"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeRepairedHostMDM) ActivityName() string {
+	return "repaired_host_mdm"
+}
+
+func (a ActivityTypeRepairedHostMDM) HostIDs() []uint {
+	return []uint{a.HostID}
+}
+
+func (a ActivityTypeRepairedHostMDM) Documentation() (activity, details, detailsExample string) {
+	return `Generated when a user repairs the MDM enrollment of a macOS, iOS or iPadOS host, which renews its SCEP identity certificate, installs fleetd again, sends its configuration profiles again and refreshes its device information.`,
+		`This activity contains the following fields:
+- "host_id": ID of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_id": 1,
+  "host_display_name": "Anna's MacBook Pro"
+}`
+}
+
+type ActivityTypeMDMUserChannelEstablished struct {
+	HostSerial      string `json:"host_serial"`
+	HostDisplayName string `json:"host_display_name"`
+}
+
+func (a ActivityTypeMDMUserChannelEstablished) ActivityName() string {
+	return "mdm_user_channel_established"
+}
+
+func (a ActivityTypeMDMUserChannelEstablished) Documentation() (activity, details, detailsExample string) {
+	return `Generated when the MDM user channel of a macOS host enrolled in Fleet's MDM is established, after a user logs in.`,
+		`This activity contains the following fields:
+- "host_serial": Serial number of the host.
+- "host_display_name": Display name of the host.`, `{
+  "host_serial": "C08VQ2AXHT96",
+  "host_display_name": "MacBookPro16,1 (C08VQ2AXHT96)"
+}`
+}
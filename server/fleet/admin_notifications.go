@@ -0,0 +1,167 @@
+package fleet
+
+import (
+	"fmt"
+	"time"
+)
+
+// AdminNotificationType is the type of the server event that raised an admin
+// notification.
+type AdminNotificationType string
+
+const (
+	// AdminNotificationAPNsCertExpiring is raised when the Apple Push
+	// Notification service certificate expires soon or has expired.
+	AdminNotificationAPNsCertExpiring AdminNotificationType = "apns_cert_expiring"
+	// AdminNotificationABMTokenInvalid is raised when the Apple Business
+	// Manager token expires soon, has expired or cannot be used.
+	AdminNotificationABMTokenInvalid AdminNotificationType = "abm_token_invalid"
+	// AdminNotificationVulnerabilitiesFailed is raised when a job of the
+	// vulnerabilities cron fails, e.g. to download the vulnerability feeds.
+	AdminNotificationVulnerabilitiesFailed AdminNotificationType = "vulnerabilities_failed"
+	// AdminNotificationCronJobFailed is raised when a job of any other cron
+	// fails.
+	AdminNotificationCronJobFailed AdminNotificationType = "cron_job_failed"
+)
+
+// AdminNotificationSeverity is the severity of an admin notification.
+type AdminNotificationSeverity string
+
+const (
+	AdminNotificationSeverityWarning AdminNotificationSeverity = "warning"
+	AdminNotificationSeverityError   AdminNotificationSeverity = "error"
+)
+
+const (
+	// AdminNotificationExpiryWarning is how long before the expiration of the
+	// APNs certificate and the ABM token the admins are notified.
+	AdminNotificationExpiryWarning = 30 * 24 * time.Hour
+	// AdminNotificationDigestInterval is the interval of the email digests of
+	// the admin notifications.
+	AdminNotificationDigestInterval = 24 * time.Hour
+	// maxAdminNotificationDetailsLength is the maximum length of the details
+	// of an admin notification, e.g. long error messages are truncated.
+	maxAdminNotificationDetailsLength = 4096
+)
+
+// AdminNotification is a persistent notification for the global admins about
+// a server event that requires their attention, e.g. an expiring APNs
+// certificate or a failing cron job.
+type AdminNotification struct {
+	ID uint `json:"id" db:"id"`
+	// Key identifies the event, the same event raised again updates the
+	// existing notification instead of creating a new one, until the
+	// notification is dismissed.
+	Key      string                    `json:"-" db:"notification_key"`
+	Type     AdminNotificationType     `json:"type" db:"type"`
+	Severity AdminNotificationSeverity `json:"severity" db:"severity"`
+	Title    string                    `json:"title" db:"title"`
+	Details  string                    `json:"details" db:"details"`
+	// Occurrences is the number of times the event was raised.
+	Occurrences uint      `json:"occurrences" db:"occurrences"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// UpdatedAt is the last time the event was raised.
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Read is true if the user listing the notifications marked it as read.
+	Read bool `json:"read" db:"read"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (n *AdminNotification) AuthzType() string {
+	return "admin_notification"
+}
+
+// NewCronJobFailedNotification returns the notification raised when the job
+// of a cron schedule fails.
+func NewCronJobFailedNotification(scheduleName, jobID string, err error) *AdminNotification {
+	typ := AdminNotificationCronJobFailed
+	title := fmt.Sprintf("The %s job of the %s cron failed", jobID, scheduleName)
+	if scheduleName == string(CronVulnerabilities) {
+		typ = AdminNotificationVulnerabilitiesFailed
+		title = fmt.Sprintf("The %s vulnerabilities job failed", jobID)
+	}
+	details := err.Error()
+	if len(details) > maxAdminNotificationDetailsLength {
+		details = details[:maxAdminNotificationDetailsLength]
+	}
+	return &AdminNotification{
+		Key:      fmt.Sprintf("%s:%s:%s", typ, scheduleName, jobID),
+		Type:     typ,
+		Severity: AdminNotificationSeverityError,
+		Title:    title,
+		Details:  details,
+	}
+}
+
+// NewAPNsCertExpiringNotification returns the notification raised when the
+// APNs certificate expires within AdminNotificationExpiryWarning of now, or
+// nil if it expires later.
+func NewAPNsCertExpiringNotification(notAfter, now time.Time) *AdminNotification {
+	return newExpiryNotification(AdminNotificationAPNsCertExpiring, "Apple Push Notification service (APNs) certificate",
+		"Renew the certificate in Apple Push Certificates Portal and restart Fleet with the renewed certificate, otherwise Apple MDM features stop working.",
+		notAfter, now)
+}
+
+// NewABMTokenExpiringNotification returns the notification raised when the
+// Apple Business Manager token expires within AdminNotificationExpiryWarning
+// of now, or nil if it expires later.
+func NewABMTokenExpiringNotification(expiry, now time.Time) *AdminNotification {
+	return newExpiryNotification(AdminNotificationABMTokenInvalid, "Apple Business Manager (ABM) token",
+		"Download a new token from Apple Business Manager and restart Fleet with the new token, otherwise hosts stop being automatically enrolled.",
+		expiry, now)
+}
+
+// NewABMTermsExpiredNotification returns the notification raised when the
+// Apple Business Manager terms and conditions were updated and must be
+// accepted.
+func NewABMTermsExpiredNotification() *AdminNotification {
+	return &AdminNotification{
+		Key:      fmt.Sprintf("%s:terms_expired", AdminNotificationABMTokenInvalid),
+		Type:     AdminNotificationABMTokenInvalid,
+		Severity: AdminNotificationSeverityError,
+		Title:    "The Apple Business Manager (ABM) terms and conditions were updated",
+		Details:  "An administrator must accept the new terms and conditions in Apple Business Manager, otherwise Fleet cannot sync the hosts from Apple Business Manager.",
+	}
+}
+
+func newExpiryNotification(typ AdminNotificationType, what, details string, expiry, now time.Time) *AdminNotification {
+	remaining := expiry.Sub(now)
+	if remaining > AdminNotificationExpiryWarning {
+		return nil
+	}
+
+	severity := AdminNotificationSeverityWarning
+	title := fmt.Sprintf("The %s expires in %d days", what, int(remaining.Hours()/24))
+	if remaining <= 0 {
+		severity = AdminNotificationSeverityError
+		title = fmt.Sprintf("The %s has expired", what)
+	}
+	return &AdminNotification{
+		// the expiry date is part of the key so that the notification of a
+		// renewed certificate or token is a new one.
+		Key:      fmt.Sprintf("%s:%s", typ, expiry.UTC().Format("2006-01-02")),
+		Type:     typ,
+		Severity: severity,
+		Title:    title,
+		Details:  fmt.Sprintf("Expiration date: %s. %s", expiry.UTC().Format("January 2, 2006"), details),
+	}
+}
+
+// AdminNotificationListOptions are the options to list the admin
+// notifications.
+type AdminNotificationListOptions struct {
+	// ListOptions are the pagination options, the notifications are always
+	// sorted by the last time they were raised, most recent first.
+	ListOptions ListOptions
+	// UnreadOnly lists only the notifications the user did not read.
+	UnreadOnly bool
+	// UpdatedSince lists only the notifications raised since that time.
+	UpdatedSince *time.Time
+}
+
+// AdminNotificationSettings are the settings of the admin notifications.
+type AdminNotificationSettings struct {
+	// DailyEmailDigest sends a daily email to each global admin with the
+	// notifications raised since the previous digest that they did not read.
+	DailyEmailDigest bool `json:"daily_email_digest"`
+}
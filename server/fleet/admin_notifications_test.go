@@ -0,0 +1,52 @@
+package fleet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCronJobFailedNotification(t *testing.T) {
+	n := NewCronJobFailedNotification(string(CronCleanupsThenAggregation), "cleanup_host_issues", errors.New("boom"))
+	require.Equal(t, AdminNotificationCronJobFailed, n.Type)
+	require.Equal(t, AdminNotificationSeverityError, n.Severity)
+	require.Equal(t, "cron_job_failed:cleanups_then_aggregation:cleanup_host_issues", n.Key)
+	require.Equal(t, "boom", n.Details)
+
+	n = NewCronJobFailedNotification(string(CronVulnerabilities), "cron_vulnerabilities", errors.New(strings.Repeat("a", 2*maxAdminNotificationDetailsLength)))
+	require.Equal(t, AdminNotificationVulnerabilitiesFailed, n.Type)
+	require.Equal(t, "vulnerabilities_failed:vulnerabilities:cron_vulnerabilities", n.Key)
+	require.Len(t, n.Details, maxAdminNotificationDetailsLength)
+}
+
+func TestNewExpiryNotifications(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	require.Nil(t, NewAPNsCertExpiringNotification(now.Add(AdminNotificationExpiryWarning+time.Hour), now))
+
+	n := NewAPNsCertExpiringNotification(now.Add(10*24*time.Hour), now)
+	require.NotNil(t, n)
+	require.Equal(t, AdminNotificationAPNsCertExpiring, n.Type)
+	require.Equal(t, AdminNotificationSeverityWarning, n.Severity)
+	require.Equal(t, "apns_cert_expiring:2024-06-20", n.Key)
+	require.Contains(t, n.Title, "expires in 10 days")
+	require.Contains(t, n.Details, "June 20, 2024")
+
+	// the key does not change from one day to the next
+	next := NewAPNsCertExpiringNotification(now.Add(10*24*time.Hour), now.Add(24*time.Hour))
+	require.Equal(t, n.Key, next.Key)
+	require.Contains(t, next.Title, "expires in 9 days")
+
+	n = NewABMTokenExpiringNotification(now.Add(-time.Hour), now)
+	require.NotNil(t, n)
+	require.Equal(t, AdminNotificationABMTokenInvalid, n.Type)
+	require.Equal(t, AdminNotificationSeverityError, n.Severity)
+	require.Contains(t, n.Title, "has expired")
+
+	n = NewABMTermsExpiredNotification()
+	require.Equal(t, AdminNotificationABMTokenInvalid, n.Type)
+	require.NotEqual(t, NewABMTokenExpiringNotification(now, now).Key, n.Key)
+}
@@ -20,6 +20,9 @@ type AgentOptions struct {
 	Extensions json.RawMessage `json:"extensions,omitempty"`
 	// UpdateChannels holds the configured channels for fleetd components.
 	UpdateChannels json.RawMessage `json:"update_channels,omitempty"`
+	// WindowsService holds the recovery policies and the watchdog settings of
+	// the fleetd service on Windows hosts.
+	WindowsService json.RawMessage `json:"windows_service,omitempty"`
 }
 
 type AgentOptionsOverrides struct {
@@ -83,6 +86,19 @@ func ValidateJSONAgentOptions(ctx context.Context, ds Datastore, rawJSON json.Ra
 		}
 	}
 
+	if len(opts.WindowsService) > 0 {
+		if string(opts.WindowsService) == "null" {
+			return errors.New("windows_service cannot be null")
+		}
+		var windowsService OrbitWindowsServiceConfig
+		if err := JSONStrictDecode(bytes.NewReader(opts.WindowsService), &windowsService); err != nil {
+			return fmt.Errorf("windows_service: %w", err)
+		}
+		if err := windowsService.Validate(); err != nil {
+			return fmt.Errorf("windows_service.%w", err)
+		}
+	}
+
 	if len(opts.Config) > 0 {
 		if err := validateJSONAgentOptionsSet(opts.Config); err != nil {
 			return fmt.Errorf("common config: %w", err)
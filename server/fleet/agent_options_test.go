@@ -184,6 +184,55 @@ func TestValidateAgentOptions(t *testing.T) {
 				"orbit": "foobar"
 			}
 		}`, true, ``},
+		{"setting an empty windows_service", `{
+			"windows_service": null
+		}`, false, `windows_service cannot be null`},
+		{"setting an unknown field in windows_service", `{
+			"windows_service": {
+				"unknown": 1
+			}
+		}`, false, `windows_service: json: unknown field "unknown"`},
+		{"setting too many windows_service recovery actions", `{
+			"windows_service": {
+				"recovery_actions": [
+					{"type": "restart", "delay_seconds": 0},
+					{"type": "restart", "delay_seconds": 60},
+					{"type": "restart", "delay_seconds": 60},
+					{"type": "none", "delay_seconds": 0}
+				]
+			}
+		}`, false, `windows_service.recovery_actions: at most 3 actions can be set`},
+		{"setting an invalid windows_service recovery action type", `{
+			"windows_service": {
+				"recovery_actions": [{"type": "reboot", "delay_seconds": 0}]
+			}
+		}`, false, `windows_service.recovery_actions[0].type: invalid type "reboot"`},
+		{"setting an invalid windows_service recovery action delay", `{
+			"windows_service": {
+				"recovery_actions": [{"type": "restart", "delay_seconds": -1}]
+			}
+		}`, false, `windows_service.recovery_actions[0].delay_seconds: must be between 0 and 86400`},
+		{"setting a negative windows_service reset period", `{
+			"windows_service": {
+				"reset_period_seconds": -1
+			}
+		}`, false, `windows_service.reset_period_seconds: must not be negative`},
+		{"setting a too short windows_service watchdog heartbeat", `{
+			"windows_service": {
+				"watchdog_heartbeat_seconds": 30
+			}
+		}`, false, `windows_service.watchdog_heartbeat_seconds: must be 0 (disabled) or at least 60`},
+		{"setting windows_service", `{
+			"windows_service": {
+				"recovery_actions": [
+					{"type": "restart", "delay_seconds": 0},
+					{"type": "restart", "delay_seconds": 60},
+					{"type": "none", "delay_seconds": 0}
+				],
+				"reset_period_seconds": 86400,
+				"watchdog_heartbeat_seconds": 300
+			}
+		}`, false, ``},
 	}
 
 	for _, c := range cases {
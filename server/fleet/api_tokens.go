@@ -0,0 +1,205 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// APITokenScope identifies the subset of the Fleet API that a team-scoped API
+// token can access.
+type APITokenScope string
+
+const (
+	// APITokenScopeInventoryRead grants read-only access to the host, software
+	// and vulnerability inventory of the team.
+	APITokenScopeInventoryRead APITokenScope = "inventory_read"
+	// APITokenScopeScripts grants access to the scripts of the team and to run
+	// them on the team's hosts.
+	APITokenScopeScripts APITokenScope = "scripts"
+	// APITokenScopeMDM grants access to the MDM features (profiles, commands,
+	// disk encryption, etc.) of the team.
+	APITokenScopeMDM APITokenScope = "mdm"
+)
+
+// apiTokenRoute is an API route an API token scope is allowed to call. An
+// empty list of methods means that any method is allowed.
+type apiTokenRoute struct {
+	methods []string
+	path    *regexp.Regexp
+}
+
+func (r apiTokenRoute) matches(method, path string) bool {
+	if !r.path.MatchString(path) {
+		return false
+	}
+	if len(r.methods) == 0 {
+		return true
+	}
+	for _, m := range r.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// apiTokenPathPrefix matches the versioned prefix of all Fleet API paths.
+const apiTokenPathPrefix = `^/api/(?:v1|latest|2022-04)/fleet`
+
+func newAPITokenRoute(path string, methods ...string) apiTokenRoute {
+	return apiTokenRoute{
+		methods: methods,
+		path:    regexp.MustCompile(apiTokenPathPrefix + path + `/?$`),
+	}
+}
+
+var apiTokenScopeRoutes = map[APITokenScope][]apiTokenRoute{
+	APITokenScopeInventoryRead: {
+		newAPITokenRoute(`/hosts(?:/.*)?`, http.MethodGet),
+		newAPITokenRoute(`/hosts/identifier/[^/]+`, http.MethodGet),
+		newAPITokenRoute(`/software(?:/.*)?`, http.MethodGet),
+		newAPITokenRoute(`/vulnerabilities(?:/.*)?`, http.MethodGet),
+		newAPITokenRoute(`/os_versions(?:/.*)?`, http.MethodGet),
+		newAPITokenRoute(`/labels(?:/.*)?`, http.MethodGet),
+	},
+	APITokenScopeScripts: {
+		newAPITokenRoute(`/hosts`, http.MethodGet),
+		newAPITokenRoute(`/hosts/[0-9]+`, http.MethodGet),
+		newAPITokenRoute(`/hosts/[0-9]+/scripts`, http.MethodGet),
+		newAPITokenRoute(`/scripts(?:/.*)?`),
+	},
+	APITokenScopeMDM: {
+		newAPITokenRoute(`/hosts`, http.MethodGet),
+		newAPITokenRoute(`/hosts/[0-9]+`, http.MethodGet),
+		newAPITokenRoute(`/hosts/[0-9]+/(?:mdm|configuration_profiles|encryption_key)`),
+		newAPITokenRoute(`/hosts/[0-9]+/configuration_profiles/.*`),
+		newAPITokenRoute(`/mdm/.*`),
+		newAPITokenRoute(`/commands(?:/.*)?`),
+		newAPITokenRoute(`/configuration_profiles(?:/.*)?`),
+		newAPITokenRoute(`/disk_encryption`, http.MethodGet),
+	},
+}
+
+// IsValid returns true if the scope is one of the supported API token scopes.
+func (s APITokenScope) IsValid() bool {
+	_, ok := apiTokenScopeRoutes[s]
+	return ok
+}
+
+// Role returns the team role that is granted to a token of that scope on its
+// team. The routes allowed for the scope further restrict what the token can
+// do with that role.
+func (s APITokenScope) Role() string {
+	switch s {
+	case APITokenScopeScripts, APITokenScopeMDM:
+		return RoleMaintainer
+	default:
+		return RoleObserver
+	}
+}
+
+// apiTokenRoleRanks orders the roles a token can act with, from the least to
+// the most privileged. The GitOps role is not part of it as it doesn't grant
+// read access.
+var apiTokenRoleRanks = map[string]int{
+	RoleObserver:     1,
+	RoleObserverPlus: 2,
+	RoleMaintainer:   3,
+	RoleAdmin:        4,
+}
+
+// RoleFor returns the team role that the token acts with on behalf of its
+// creator: the role granted by its scope, capped at the role the creator
+// currently has on the token's team (or their global role). It returns an
+// empty string if the creator no longer has a role that gives access to the
+// team, e.g. after they were removed from it.
+func (t *APIToken) RoleFor(creator *User) string {
+	var creatorRole string
+	if creator.GlobalRole != nil {
+		creatorRole = *creator.GlobalRole
+	} else {
+		for _, ut := range creator.Teams {
+			if ut.ID == t.TeamID {
+				creatorRole = ut.Role
+				break
+			}
+		}
+	}
+
+	creatorRank, ok := apiTokenRoleRanks[creatorRole]
+	if !ok {
+		return ""
+	}
+	scopeRole := t.Scope.Role()
+	if creatorRank < apiTokenRoleRanks[scopeRole] {
+		return creatorRole
+	}
+	return scopeRole
+}
+
+// AllowsRequest returns true if a token with that scope is allowed to make a
+// request with the provided HTTP method to the provided URL path.
+func (s APITokenScope) AllowsRequest(method, path string) bool {
+	for _, r := range apiTokenScopeRoutes[s] {
+		if r.matches(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a secret used by integrations to access a specific subset
+// (scope) of the Fleet API for a single team, without impersonating a user.
+type APIToken struct {
+	CreateTimestamp
+
+	ID     uint          `json:"id" db:"id"`
+	Name   string        `json:"name" db:"name"`
+	TeamID uint          `json:"team_id" db:"team_id"`
+	Scope  APITokenScope `json:"scope" db:"scope"`
+	// CreatedByUserID is the ID of the user that created the token. Tokens are
+	// deleted along with the user that created them.
+	CreatedByUserID uint       `json:"created_by_user_id" db:"created_by_user_id"`
+	LastUsedAt      *time.Time `json:"last_used_at" db:"last_used_at"`
+
+	// Token is the secret itself. It is only ever returned once, when the token
+	// is created, only its hash is stored.
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (t *APIToken) AuthzType() string {
+	return "api_token"
+}
+
+// APITokenPayload is the payload used to create a new API token.
+type APITokenPayload struct {
+	Name   string        `json:"name"`
+	TeamID uint          `json:"team_id"`
+	Scope  APITokenScope `json:"scope"`
+}
+
+// Validate validates the API token payload.
+func (p APITokenPayload) Validate() error {
+	if p.Name == "" {
+		return NewInvalidArgumentError("name", "API token name cannot be empty")
+	}
+	if p.TeamID == 0 {
+		return NewInvalidArgumentError("team_id", "API tokens must be bound to a team")
+	}
+	if !p.Scope.IsValid() {
+		return NewInvalidArgumentError("scope", fmt.Sprintf("unsupported API token scope %q", p.Scope))
+	}
+	return nil
+}
+
+// HashAPITokenSecret returns the hash of an API token secret, as stored in the
+// datastore.
+func HashAPITokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenScopeAllowsRequest(t *testing.T) {
+	cases := []struct {
+		scope  APITokenScope
+		method string
+		path   string
+		want   bool
+	}{
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/v1/fleet/hosts", true},
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/latest/fleet/hosts/123", true},
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/latest/fleet/software/titles", true},
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/latest/fleet/vulnerabilities", true},
+		{APITokenScopeInventoryRead, http.MethodDelete, "/api/latest/fleet/hosts/123", false},
+		{APITokenScopeInventoryRead, http.MethodPost, "/api/latest/fleet/scripts/run", false},
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/latest/fleet/config", false},
+		{APITokenScopeInventoryRead, http.MethodGet, "/api/latest/fleet/hostsfoo", false},
+
+		{APITokenScopeScripts, http.MethodPost, "/api/latest/fleet/scripts/run", true},
+		{APITokenScopeScripts, http.MethodGet, "/api/latest/fleet/scripts", true},
+		{APITokenScopeScripts, http.MethodGet, "/api/latest/fleet/hosts/1/scripts", true},
+		{APITokenScopeScripts, http.MethodDelete, "/api/latest/fleet/hosts/1", false},
+		{APITokenScopeScripts, http.MethodGet, "/api/latest/fleet/software", false},
+
+		{APITokenScopeMDM, http.MethodPost, "/api/latest/fleet/commands/run", true},
+		{APITokenScopeMDM, http.MethodGet, "/api/latest/fleet/hosts/1/encryption_key", true},
+		{APITokenScopeMDM, http.MethodPost, "/api/latest/fleet/mdm/hosts/1/lock", true},
+		{APITokenScopeMDM, http.MethodPost, "/api/latest/fleet/scripts/run", false},
+		{APITokenScopeMDM, http.MethodPatch, "/api/latest/fleet/config", false},
+
+		{APITokenScope("nope"), http.MethodGet, "/api/latest/fleet/hosts", false},
+		{APITokenScopeInventoryRead, http.MethodGet, "/debug/pprof", false},
+	}
+	for _, c := range cases {
+		t.Run(string(c.scope)+" "+c.method+" "+c.path, func(t *testing.T) {
+			require.Equal(t, c.want, c.scope.AllowsRequest(c.method, c.path))
+		})
+	}
+}
+
+func TestAPITokenPayloadValidate(t *testing.T) {
+	require.NoError(t, APITokenPayload{Name: "a", TeamID: 1, Scope: APITokenScopeMDM}.Validate())
+	require.ErrorContains(t, APITokenPayload{TeamID: 1, Scope: APITokenScopeMDM}.Validate(), "name")
+	require.ErrorContains(t, APITokenPayload{Name: "a", Scope: APITokenScopeMDM}.Validate(), "team_id")
+	require.ErrorContains(t, APITokenPayload{Name: "a", TeamID: 1, Scope: "admin"}.Validate(), "scope")
+}
+
+func TestAPITokenScopeRole(t *testing.T) {
+	require.Equal(t, RoleObserver, APITokenScopeInventoryRead.Role())
+	require.Equal(t, RoleMaintainer, APITokenScopeScripts.Role())
+	require.Equal(t, RoleMaintainer, APITokenScopeMDM.Role())
+}
+
+func TestAPITokenRoleFor(t *testing.T) {
+	tok := &APIToken{TeamID: 1, Scope: APITokenScopeScripts}
+	readTok := &APIToken{TeamID: 1, Scope: APITokenScopeInventoryRead}
+
+	globalUser := func(role string) *User { return &User{GlobalRole: ptr.String(role)} }
+	teamUser := func(teamID uint, role string) *User {
+		return &User{Teams: []UserTeam{{Team: Team{ID: 2}, Role: RoleAdmin}, {Team: Team{ID: teamID}, Role: role}}}
+	}
+
+	cases := []struct {
+		desc    string
+		tok     *APIToken
+		creator *User
+		want    string
+	}{
+		{"global admin", tok, globalUser(RoleAdmin), RoleMaintainer},
+		{"global admin read", readTok, globalUser(RoleAdmin), RoleObserver},
+		{"global maintainer", tok, globalUser(RoleMaintainer), RoleMaintainer},
+		{"global observer", tok, globalUser(RoleObserver), RoleObserver},
+		{"global gitops", tok, globalUser(RoleGitOps), ""},
+		{"team admin", tok, teamUser(1, RoleAdmin), RoleMaintainer},
+		{"demoted to team observer plus", tok, teamUser(1, RoleObserverPlus), RoleObserverPlus},
+		{"demoted to team observer read", readTok, teamUser(1, RoleObserver), RoleObserver},
+		{"removed from team", tok, teamUser(3, RoleAdmin), ""},
+		{"no role", tok, &User{}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, c.tok.RoleFor(c.creator))
+		})
+	}
+}
@@ -301,6 +301,9 @@ type MacOSSettings struct {
 	// (The source of truth for profiles is in MySQL.)
 	CustomSettings                 []MDMProfileSpec `json:"custom_settings"`
 	DeprecatedEnableDiskEncryption *bool            `json:"enable_disk_encryption,omitempty"`
+	// EraseDevice are the options of the EraseDevice MDM command sent to wipe
+	// the hosts, the defaults are used if nil.
+	EraseDevice *MDMAppleEraseDeviceOptions `json:"erase_device,omitempty"`
 
 	// NOTE: make sure to update the ToMap/FromMap methods when adding/updating fields.
 }
@@ -309,6 +312,7 @@ func (s MacOSSettings) ToMap() map[string]interface{} {
 	return map[string]interface{}{
 		"custom_settings":        s.CustomSettings,
 		"enable_disk_encryption": s.DeprecatedEnableDiskEncryption,
+		"erase_device":           s.EraseDevice,
 	}
 }
 
@@ -373,6 +377,23 @@ func (s *MacOSSettings) FromMap(m map[string]interface{}) (map[string]bool, erro
 		s.DeprecatedEnableDiskEncryption = ptr.Bool(b)
 	}
 
+	if v, ok := m["erase_device"]; ok {
+		set["erase_device"] = true
+		s.EraseDevice = nil
+		if v != nil {
+			// the map comes from JSON, re-encode it to decode the options
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			var opts MDMAppleEraseDeviceOptions
+			if err := JSONStrictDecode(bytes.NewReader(b), &opts); err != nil {
+				return nil, fmt.Errorf("macos_settings.erase_device: %w", err)
+			}
+			s.EraseDevice = &opts
+		}
+	}
+
 	return set, nil
 }
 
@@ -437,6 +458,31 @@ type AppConfig struct {
 	SMTPSettings           *SMTPSettings          `json:"smtp_settings,omitempty"`
 	HostExpirySettings     HostExpirySettings     `json:"host_expiry_settings"`
 	ActivityExpirySettings ActivityExpirySettings `json:"activity_expiry_settings"`
+	SessionSettings        SessionSettings        `json:"session_settings"`
+	MFASettings            MFASettings            `json:"mfa_settings"`
+	// EnrollmentApproval holds the settings of the enrollment approval mode.
+	EnrollmentApproval EnrollmentApprovalSettings `json:"enrollment_approval"`
+	// DuplicateHosts holds the settings of the duplicate hosts merge.
+	DuplicateHosts DuplicateHostsSettings `json:"duplicate_hosts"`
+	// FileRetrieval holds the file retrieval settings for hosts with no team.
+	FileRetrieval FileRetrievalSettings `json:"file_retrieval"`
+	// LockWipeSettings holds the lock and wipe settings for hosts with no team.
+	LockWipeSettings LockWipeSettings `json:"lock_wipe_settings"`
+	// RemoteQueries holds the remote queries settings for hosts with no team.
+	RemoteQueries RemoteQuerySettings `json:"remote_queries"`
+	// VulnerabilitySLA holds the vulnerability remediation SLAs for hosts with
+	// no team.
+	VulnerabilitySLA VulnerabilitySLASettings `json:"vulnerability_sla"`
+	// HostCustomFields defines the custom fields that can be set on hosts.
+	HostCustomFields []HostCustomFieldDefinition `json:"host_custom_fields"`
+	// OrbitFeatureFlags defines the feature flags sent to fleetd agents.
+	OrbitFeatureFlags []OrbitFeatureFlag `json:"orbit_feature_flags"`
+	// AdminNotifications holds the settings of the admin notifications.
+	AdminNotifications AdminNotificationSettings `json:"admin_notifications"`
+	// GitOpsMode holds the settings of the GitOps mode.
+	GitOpsMode GitOpsModeSettings `json:"gitops"`
+	// Localization holds the locale of the end-user facing messages.
+	Localization LocalizationSettings `json:"localization"`
 	// Features allows to globally enable or disable features
 	Features               Features  `json:"features"`
 	DeprecatedHostSettings *Features `json:"host_settings,omitempty"`
@@ -491,6 +537,9 @@ func (c *AppConfig) Obfuscate() {
 	for _, zdIntegration := range c.Integrations.Zendesk {
 		zdIntegration.APIToken = MaskedPassword
 	}
+	for _, warrantyIntegration := range c.Integrations.Warranty {
+		warrantyIntegration.APIKey = MaskedPassword
+	}
 }
 
 // Clone implements cloner.
@@ -522,6 +571,38 @@ func (c *AppConfig) Copy() *AppConfig {
 	}
 
 	// HostExpirySettings: nothing needs cloning
+	// SessionSettings: nothing needs cloning
+	// MFASettings: nothing needs cloning
+	// EnrollmentApproval: nothing needs cloning
+	// DuplicateHosts: nothing needs cloning
+	// LockWipeSettings: nothing needs cloning
+	// RemoteQueries: nothing needs cloning
+	// VulnerabilitySLA: nothing needs cloning
+	// GitOpsMode: nothing needs cloning
+	// Localization: nothing needs cloning
+
+	if c.HostCustomFields != nil {
+		clone.HostCustomFields = make([]HostCustomFieldDefinition, len(c.HostCustomFields))
+		copy(clone.HostCustomFields, c.HostCustomFields)
+	}
+
+	if c.OrbitFeatureFlags != nil {
+		clone.OrbitFeatureFlags = make([]OrbitFeatureFlag, len(c.OrbitFeatureFlags))
+		for i, f := range c.OrbitFeatureFlags {
+			if f.TeamIDs != nil {
+				f.TeamIDs = append([]uint(nil), f.TeamIDs...)
+			}
+			if f.Percentage != nil {
+				f.Percentage = ptr.Int(*f.Percentage)
+			}
+			clone.OrbitFeatureFlags[i] = f
+		}
+	}
+
+	if c.FileRetrieval.AllowedPaths != nil {
+		clone.FileRetrieval.AllowedPaths = make([]string, len(c.FileRetrieval.AllowedPaths))
+		copy(clone.FileRetrieval.AllowedPaths, c.FileRetrieval.AllowedPaths)
+	}
 
 	if c.Features.AdditionalQueries != nil {
 		aq := make(json.RawMessage, len(*c.Features.AdditionalQueries))
@@ -580,6 +661,13 @@ func (c *AppConfig) Copy() *AppConfig {
 			maps.Copy(clone.Integrations.GoogleCalendar[i].ApiKey, g.ApiKey)
 		}
 	}
+	if c.Integrations.Warranty != nil {
+		clone.Integrations.Warranty = make([]*WarrantyIntegration, len(c.Integrations.Warranty))
+		for i, w := range c.Integrations.Warranty {
+			warranty := *w
+			clone.Integrations.Warranty[i] = &warranty
+		}
+	}
 
 	if c.MDM.MacOSSettings.CustomSettings != nil {
 		clone.MDM.MacOSSettings.CustomSettings = make([]MDMProfileSpec, len(c.MDM.MacOSSettings.CustomSettings))
@@ -591,6 +679,10 @@ func (c *AppConfig) Copy() *AppConfig {
 		b := *c.MDM.MacOSSettings.DeprecatedEnableDiskEncryption
 		clone.MDM.MacOSSettings.DeprecatedEnableDiskEncryption = &b
 	}
+	if c.MDM.MacOSSettings.EraseDevice != nil {
+		opts := *c.MDM.MacOSSettings.EraseDevice
+		clone.MDM.MacOSSettings.EraseDevice = &opts
+	}
 
 	if c.Scripts.Set {
 		scripts := make([]string, len(c.Scripts.Value))
@@ -705,12 +797,15 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 }
 
 type WebhookSettings struct {
-	HostStatusWebhook      HostStatusWebhookSettings      `json:"host_status_webhook"`
-	FailingPoliciesWebhook FailingPoliciesWebhookSettings `json:"failing_policies_webhook"`
-	VulnerabilitiesWebhook VulnerabilitiesWebhookSettings `json:"vulnerabilities_webhook"`
+	HostStatusWebhook        HostStatusWebhookSettings        `json:"host_status_webhook"`
+	FailingPoliciesWebhook   FailingPoliciesWebhookSettings   `json:"failing_policies_webhook"`
+	VulnerabilitiesWebhook   VulnerabilitiesWebhookSettings   `json:"vulnerabilities_webhook"`
+	CertificateExpiryWebhook CertificateExpiryWebhookSettings `json:"certificate_expiry_webhook"`
+	TamperEventsWebhook      TamperEventsWebhookSettings      `json:"tamper_events_webhook"`
+	MDMEnrollmentWebhook     MDMEnrollmentWebhookSettings     `json:"mdm_enrollment_webhook"`
 	// Interval is the interval for running the webhooks.
 	//
-	// This value currently configures both the host status and failing policies webhooks.
+	// This value currently configures the host status, failing policies and certificate expiry webhooks.
 	Interval Duration `json:"interval"`
 }
 
@@ -745,6 +840,49 @@ type VulnerabilitiesWebhookSettings struct {
 	HostBatchSize int `json:"host_batch_size"`
 }
 
+// CertificateExpiryWebhookSettings holds the settings for the certificates
+// expiry webhook.
+type CertificateExpiryWebhookSettings struct {
+	// Enable indicates whether the webhook for expiring certificates is enabled.
+	Enable bool `json:"enable_certificate_expiry_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+	// DaysBeforeExpiry is the number of days before expiry at which a
+	// certificate is reported.
+	DaysBeforeExpiry int `json:"days_before_expiry"`
+	// HostsThreshold is the number of hosts a certificate must be found on (it
+	// is reported only if found on more hosts than that).
+	HostsThreshold int `json:"hosts_threshold"`
+}
+
+// TamperEventsWebhookSettings holds the settings for the tamper events
+// webhook. Unlike the other webhooks, it is triggered as soon as a tamper
+// event is reported by a host.
+type TamperEventsWebhookSettings struct {
+	// Enable indicates whether the webhook for tamper events is enabled.
+	Enable bool `json:"enable_tamper_events_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+}
+
+// MDMEnrollmentWebhookSettings holds the settings for the MDM enrollment
+// webhook. Like the tamper events webhook, it is triggered as soon as an Apple
+// host enrolls or unenrolls from Fleet's MDM.
+type MDMEnrollmentWebhookSettings struct {
+	// Enable indicates whether the webhook for MDM enrollment events is
+	// enabled.
+	Enable bool `json:"enable_mdm_enrollment_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+}
+
+// MDM enrollment events sent to the MDM enrollment webhook.
+const (
+	MDMEnrollmentEventEnrolled               = "enrolled"
+	MDMEnrollmentEventUnenrolled             = "unenrolled"
+	MDMEnrollmentEventUserChannelEstablished = "user_channel_established"
+)
+
 func (c *AppConfig) ApplyDefaultsForNewInstalls() {
 	c.ServerSettings.EnableAnalytics = true
 
@@ -868,19 +1006,29 @@ type OrgInfo struct {
 	// ContactURL is the URL displayed for users to contact support. By default,
 	// https://fleetdm.com/company/contact is used.
 	ContactURL string `json:"contact_url"`
+	// PrimaryColor is the hex color (e.g. #192147) of the buttons and accents
+	// of the end-user facing pages. The default Fleet color is used if empty.
+	PrimaryColor string `json:"primary_color"`
+	// BackgroundColor is the hex color of the background of the end-user
+	// facing pages. The default Fleet color is used if empty.
+	BackgroundColor string `json:"background_color"`
 }
 
 const DefaultOrgInfoContactURL = "https://fleetdm.com/company/contact"
 
 // ServerSettings contains general settings about the Fleet application.
 type ServerSettings struct {
-	ServerURL            string `json:"server_url"`
-	LiveQueryDisabled    bool   `json:"live_query_disabled"`
-	EnableAnalytics      bool   `json:"enable_analytics"`
-	DebugHostIDs         []uint `json:"debug_host_ids,omitempty"`
-	DeferredSaveHost     bool   `json:"deferred_save_host"`
-	QueryReportsDisabled bool   `json:"query_reports_disabled"`
-	ScriptsDisabled      bool   `json:"scripts_disabled"`
+	ServerURL         string `json:"server_url"`
+	LiveQueryDisabled bool   `json:"live_query_disabled"`
+	EnableAnalytics   bool   `json:"enable_analytics"`
+	// DetailedUsageAnalytics enables the collection of the detailed usage
+	// analytics (API requests and host counts over time) shown in the local
+	// usage analytics dashboard. They are never sent to Fleet.
+	DetailedUsageAnalytics bool   `json:"detailed_usage_analytics"`
+	DebugHostIDs           []uint `json:"debug_host_ids,omitempty"`
+	DeferredSaveHost       bool   `json:"deferred_save_host"`
+	QueryReportsDisabled   bool   `json:"query_reports_disabled"`
+	ScriptsDisabled        bool   `json:"scripts_disabled"`
 }
 
 // HostExpirySettings contains settings pertaining to automatic host expiry.
@@ -895,11 +1043,36 @@ type ActivityExpirySettings struct {
 	ActivityExpiryWindow  int  `json:"activity_expiry_window"`
 }
 
+// SessionSettings contains settings pertaining to the expiration of user
+// sessions. API-only users' sessions never expire.
+type SessionSettings struct {
+	// MaxSessionLifetime is the maximum number of minutes a session can be used
+	// after it was created, regardless of activity. Zero means no maximum.
+	MaxSessionLifetime int `json:"max_session_lifetime"`
+	// IdleTimeout is the number of minutes after which an unused session
+	// expires. Zero means that the server's session duration configuration
+	// is used.
+	IdleTimeout int `json:"idle_timeout"`
+}
+
+// MFASettings contains settings pertaining to multi-factor authentication of
+// password-based users.
+type MFASettings struct {
+	// Required forces password-based users to enroll a second factor before
+	// they can use Fleet. SSO and API-only users are not affected.
+	Required bool `json:"required"`
+}
+
 type Features struct {
 	EnableHostUsers         bool               `json:"enable_host_users"`
 	EnableSoftwareInventory bool               `json:"enable_software_inventory"`
 	AdditionalQueries       *json.RawMessage   `json:"additional_queries,omitempty"`
 	DetailQueryOverrides    map[string]*string `json:"detail_query_overrides,omitempty"`
+	// RequireMDMEnrollment withholds the scheduled queries and the policies
+	// from the macOS and Windows hosts that are not enrolled in Fleet MDM,
+	// osquery only gets the agent options and the queries that collect the
+	// host's vitals.
+	RequireMDMEnrollment bool `json:"require_mdm_enrollment"`
 
 	/////////////////////////////////////////////////////////////////
 	// WARNING: If you add to this struct make sure it's taken into
@@ -1063,6 +1236,13 @@ type EnrollSecret struct {
 	// TeamID is the ID for the associated team. If no ID is set, then this is a
 	// global enroll secret.
 	TeamID *uint `json:"team_id,omitempty" db:"team_id"`
+	// Labels are the names of the manual labels that the hosts are added to
+	// when they enroll with this secret.
+	Labels SliceString `json:"labels,omitempty" db:"labels"`
+	// CustomFields are the values of the host custom fields that are set on
+	// the hosts when they enroll with this secret, if they don't have a value
+	// already.
+	CustomFields HostCustomFieldValues `json:"custom_fields,omitempty" db:"custom_fields"`
 }
 
 func (e *EnrollSecret) AuthzType() string {
@@ -5,6 +5,7 @@ import (
 	"crypto/md5" // nolint: gosec
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -19,8 +20,13 @@ type MDMAppleCommandIssuer interface {
 	InstallProfile(ctx context.Context, hostUUIDs []string, profile mobileconfig.Mobileconfig, uuid string) error
 	RemoveProfile(ctx context.Context, hostUUIDs []string, identifier string, uuid string) error
 	DeviceLock(ctx context.Context, host *Host, uuid string) error
-	EraseDevice(ctx context.Context, host *Host, uuid string) error
+	EraseDevice(ctx context.Context, host *Host, uuid string, opts MDMAppleEraseDeviceOptions) error
 	InstallEnterpriseApplication(ctx context.Context, hostUUIDs []string, uuid string, manifestURL string) error
+	EnableLostMode(ctx context.Context, hostUUIDs []string, uuid string, settings HostLostModeSettings) error
+	DisableLostMode(ctx context.Context, hostUUIDs []string, uuid string) error
+	DeviceLocation(ctx context.Context, hostUUIDs []string, uuid string) error
+	ClearActivationLockBypassCode(ctx context.Context, hostUUIDs []string, uuid string) error
+	SetFirmwarePassword(ctx context.Context, host *Host, uuid, currentPassword string) error
 }
 
 // MDMAppleEnrollmentType is the type for Apple MDM enrollments.
@@ -42,6 +48,31 @@ const (
 	MDMAppleStatusNotNow             = "NotNow"
 )
 
+// RefetchMDMCommandUUIDPrefix is the prefix of the UUIDs of the commands
+// enqueued to refetch the details of the hosts managed purely via MDM, such
+// as iPhones and iPads.
+const RefetchMDMCommandUUIDPrefix = "REFETCH-"
+
+// RefetchIOSAndIPadOSInterval is the interval at which the details of
+// iPhones and iPads are refetched via MDM commands.
+const RefetchIOSAndIPadOSInterval = time.Hour
+
+// RefetchMDMSoftwareInterval is the interval at which the software of the
+// Apple hosts not enrolled in osquery is refetched via the
+// InstalledApplicationList MDM command.
+const RefetchMDMSoftwareInterval = time.Hour
+
+const (
+	// RetryNotNowInstallApplicationInterval is the interval after which the
+	// devices that responded NotNow to an InstallApplication command are sent
+	// a push notification again, so that they retry the install.
+	RetryNotNowInstallApplicationInterval = 15 * time.Minute
+	// MaxNotNowInstallApplicationAge is the age after which the
+	// InstallApplication commands still in NotNow are not retried anymore,
+	// they are still delivered on the next check-in of the devices.
+	MaxNotNowInstallApplicationAge = 7 * 24 * time.Hour
+)
+
 // MDMAppleEnrollmentProfilePayload contains the data necessary to create
 // an enrollment profile in Fleet.
 type MDMAppleEnrollmentProfilePayload struct {
@@ -196,10 +227,21 @@ type MDMAppleConfigProfile struct {
 	Mobileconfig mobileconfig.Mobileconfig `db:"mobileconfig" json:"-"`
 	// Checksum is an MD5 hash of the Mobileconfig bytes
 	Checksum []byte `db:"checksum" json:"checksum,omitempty"`
-	// Labels are the associated labels for this profile
-	Labels     []ConfigurationProfileLabel `db:"labels" json:"labels,omitempty"`
-	CreatedAt  time.Time                   `db:"created_at" json:"created_at"`
-	UploadedAt time.Time                   `db:"uploaded_at" json:"updated_at"` // NOTE: JSON field is still `updated_at` for historical reasons, would be an API breaking change
+	// Labels are the associated labels for this profile, the hosts must be
+	// members of all of them to receive the profile.
+	Labels []ConfigurationProfileLabel `db:"labels" json:"labels,omitempty"`
+	// LabelsIncludeAny are the labels that the hosts must be a member of at
+	// least one of to receive the profile.
+	LabelsIncludeAny []ConfigurationProfileLabel `db:"-" json:"labels_include_any,omitempty"`
+	// LabelsExcludeAny are the labels that the hosts must not be a member of
+	// to receive the profile.
+	LabelsExcludeAny []ConfigurationProfileLabel `db:"-" json:"labels_exclude_any,omitempty"`
+	// Rollout are the staged rollout settings to apply when the profile is
+	// created or its contents change, nil to deliver it to all the targeted
+	// hosts at once.
+	Rollout    *MDMProfileRolloutSettings `db:"-" json:"-"`
+	CreatedAt  time.Time                  `db:"created_at" json:"created_at"`
+	UploadedAt time.Time                  `db:"uploaded_at" json:"updated_at"` // NOTE: JSON field is still `updated_at` for historical reasons, would be an API breaking change
 }
 
 // ConfigurationProfileLabel represents the many-to-many relationship between
@@ -212,6 +254,54 @@ type ConfigurationProfileLabel struct {
 	LabelName   string `db:"label_name" json:"name"`
 	LabelID     uint   `db:"label_id" json:"id,omitempty"`   // omitted if 0 (which is impossible if the label is not broken)
 	Broken      bool   `db:"broken" json:"broken,omitempty"` // omitted (not rendered to JSON) if false
+	// Exclude is true if the hosts that are members of the label must not
+	// receive the profile.
+	Exclude bool `db:"exclude" json:"-"`
+	// RequireAll is true if the hosts must be members of this label and all
+	// the other labels of the profile with RequireAll set, false if they must
+	// be a member of any of the labels without it. It is ignored when Exclude
+	// is true.
+	RequireAll bool `db:"require_all" json:"-"`
+}
+
+// JoinConfigurationProfileLabels returns the labels of a profile with their
+// Exclude and RequireAll fields set from the set they belong to, so that they
+// can be saved together.
+func JoinConfigurationProfileLabels(includeAll, includeAny, excludeAny []ConfigurationProfileLabel) []ConfigurationProfileLabel {
+	if len(includeAll)+len(includeAny)+len(excludeAny) == 0 {
+		return nil
+	}
+	labels := make([]ConfigurationProfileLabel, 0, len(includeAll)+len(includeAny)+len(excludeAny))
+	for _, l := range includeAll {
+		l.Exclude, l.RequireAll = false, true
+		labels = append(labels, l)
+	}
+	for _, l := range includeAny {
+		l.Exclude, l.RequireAll = false, false
+		labels = append(labels, l)
+	}
+	for _, l := range excludeAny {
+		l.Exclude, l.RequireAll = true, false
+		labels = append(labels, l)
+	}
+	return labels
+}
+
+// SplitConfigurationProfileLabels is the reverse of
+// JoinConfigurationProfileLabels, it returns the labels that the hosts must
+// be members of all of, of any of, and of none of.
+func SplitConfigurationProfileLabels(labels []ConfigurationProfileLabel) (includeAll, includeAny, excludeAny []ConfigurationProfileLabel) {
+	for _, l := range labels {
+		switch {
+		case l.Exclude:
+			excludeAny = append(excludeAny, l)
+		case l.RequireAll:
+			includeAll = append(includeAll, l)
+		default:
+			includeAny = append(includeAny, l)
+		}
+	}
+	return includeAll, includeAny, excludeAny
 }
 
 func NewMDMAppleConfigProfile(raw []byte, teamID *uint) (*MDMAppleConfigProfile, error) {
@@ -454,6 +544,105 @@ type NanoEnrollment struct {
 	TokenUpdateTally int    `json:"-" db:"token_update_tally"`
 }
 
+// IsUserEnrollment returns true if the enrollment is an Apple User Enrollment,
+// i.e. a personally-owned (BYOD) device enrolled with a Managed Apple ID. For
+// privacy reasons, Apple restricts the commands that can be sent to such
+// devices, see MDMAppleUserEnrollmentCommandError.
+func (e *NanoEnrollment) IsUserEnrollment() bool {
+	return e.Type == NanoEnrollmentTypeUserEnrollmentDevice || e.Type == NanoEnrollmentTypeUserEnrollment
+}
+
+const (
+	// NanoEnrollmentTypeUserEnrollmentDevice is the nano_enrollments type of
+	// the device channel of an Apple User Enrollment.
+	NanoEnrollmentTypeUserEnrollmentDevice = "User Enrollment (Device)"
+	// NanoEnrollmentTypeUserEnrollment is the nano_enrollments type of the user
+	// channel of an Apple User Enrollment.
+	NanoEnrollmentTypeUserEnrollment = "User Enrollment"
+)
+
+// Reasons why an Apple MDM command can't be sent to User Enrollment (BYOD)
+// devices, along with the allowed alternative if there is one.
+const (
+	MDMAppleUserEnrollmentEraseMessage   = "Apple doesn't allow erasing personally-owned hosts. To remove the work data from the host, turn off MDM instead."
+	MDMAppleUserEnrollmentLockPINMessage = "Apple doesn't allow locking personally-owned hosts with a PIN. To lock the host, send the DeviceLock command without a PIN instead."
+	MDMAppleUserEnrollmentAppListMessage = "Apple doesn't allow listing all the apps installed on personally-owned hosts. To list the managed apps, send the InstalledApplicationList command with ManagedAppsOnly set to true instead."
+)
+
+// Values of the ObliterationBehavior of the EraseDevice MDM command, see
+// https://developer.apple.com/documentation/devicemanagement/erasedevicecommand/command
+const (
+	MDMAppleObliterationBehaviorDefault               = "Default"
+	MDMAppleObliterationBehaviorDoNotObliterate       = "DoNotObliterate"
+	MDMAppleObliterationBehaviorObliterateWithWarning = "ObliterateWithWarning"
+	MDMAppleObliterationBehaviorAlways                = "Always"
+)
+
+// Bounds of the length of the PIN of the EraseDevice MDM command.
+const (
+	MDMAppleEraseDeviceMinPINLength     = 4
+	MDMAppleEraseDeviceMaxPINLength     = 6
+	MDMAppleEraseDeviceDefaultPINLength = 6
+)
+
+// MDMAppleEraseDeviceOptions are the options of the EraseDevice MDM command
+// sent to wipe Apple hosts. The zero value sends the command with the Default
+// obliteration behavior and a 6-digit PIN.
+type MDMAppleEraseDeviceOptions struct {
+	// ObliterationBehavior is the behavior of the device when the erase
+	// fails, one of the MDMAppleObliterationBehavior* values. It is only
+	// used by Intel Macs with a T2 chip, empty means Default.
+	ObliterationBehavior string `json:"obliteration_behavior"`
+	// PINLength is the number of digits of the generated Find My PIN,
+	// 0 means MDMAppleEraseDeviceDefaultPINLength.
+	PINLength int `json:"pin_length"`
+	// SkipPIN sends the command without a PIN. The PIN is ignored by Apple
+	// silicon Macs and Intel Macs with a T2 chip.
+	SkipPIN bool `json:"skip_pin"`
+}
+
+// Validate returns an error if the options are invalid. A nil options is
+// valid.
+func (o *MDMAppleEraseDeviceOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.ObliterationBehavior {
+	case "", MDMAppleObliterationBehaviorDefault, MDMAppleObliterationBehaviorDoNotObliterate,
+		MDMAppleObliterationBehaviorObliterateWithWarning, MDMAppleObliterationBehaviorAlways:
+	default:
+		return fmt.Errorf("obliteration_behavior must be one of %q, %q, %q or %q", MDMAppleObliterationBehaviorDefault,
+			MDMAppleObliterationBehaviorDoNotObliterate, MDMAppleObliterationBehaviorObliterateWithWarning, MDMAppleObliterationBehaviorAlways)
+	}
+	if o.PINLength != 0 && (o.PINLength < MDMAppleEraseDeviceMinPINLength || o.PINLength > MDMAppleEraseDeviceMaxPINLength) {
+		return fmt.Errorf("pin_length must be between %d and %d", MDMAppleEraseDeviceMinPINLength, MDMAppleEraseDeviceMaxPINLength)
+	}
+	if o.SkipPIN && o.PINLength != 0 {
+		return errors.New("pin_length can't be set when skip_pin is true")
+	}
+	return nil
+}
+
+// MDMAppleUserEnrollmentCommandRestriction returns the reason why an Apple MDM
+// command with the provided request type and payload fields can't be sent to
+// a User Enrollment (BYOD) device. It returns an empty string if the command
+// is allowed.
+func MDMAppleUserEnrollmentCommandRestriction(requestType, pin string, managedAppsOnly bool) string {
+	switch requestType {
+	case "EraseDevice":
+		return MDMAppleUserEnrollmentEraseMessage
+	case "DeviceLock":
+		if pin != "" {
+			return MDMAppleUserEnrollmentLockPINMessage
+		}
+	case "InstalledApplicationList":
+		if !managedAppsOnly {
+			return MDMAppleUserEnrollmentAppListMessage
+		}
+	}
+	return ""
+}
+
 // MDMAppleCommand represents an MDM Apple command that has been enqueued for
 // execution. It is similar to MDMAppleCommandResult, but a separate struct is
 // used as there are plans to evolve the `fleetctl get mdm-commands` command
@@ -523,6 +712,38 @@ type SCEPIdentityAssociation struct {
 	SHA256           string `db:"sha256"`
 	EnrollReference  string `db:"enroll_reference"`
 	RenewCommandUUID string `db:"renew_command_uuid"`
+	// Topic is the APNs topic the host is enrolled with, which is the topic
+	// of its tenant's APNs certificate if the tenant has one.
+	Topic string `db:"topic"`
+}
+
+// MDMAppleHostRepair is the result of the repair of the MDM enrollment of an
+// Apple host, with the UUIDs of the commands sent to the host.
+type MDMAppleHostRepair struct {
+	// SCEPRenewalCommandUUID is the UUID of the command that renews the SCEP
+	// identity certificate of the host, empty if the host has no identity
+	// certificate.
+	SCEPRenewalCommandUUID string `json:"scep_renewal_command_uuid,omitempty"`
+	// FleetdInstallCommandUUID is the UUID of the command that installs
+	// fleetd, only sent to macOS hosts.
+	FleetdInstallCommandUUID string `json:"fleetd_install_command_uuid,omitempty"`
+	// ResentProfiles is the number of configuration profiles that are sent
+	// again to the host.
+	ResentProfiles               int    `json:"resent_profiles"`
+	DeviceInformationCommandUUID string `json:"device_information_command_uuid"`
+}
+
+// HostMDMAppleProfileCertificate is a Wi-Fi or VPN configuration profile
+// installed on a host that authenticates with a certificate issued by a SCEP
+// payload of the same profile. InstalledAt is when the host acknowledged the
+// installation of the profile, which is when it got the certificate.
+type HostMDMAppleProfileCertificate struct {
+	HostUUID          string    `db:"host_uuid"`
+	ProfileUUID       string    `db:"profile_uuid"`
+	ProfileIdentifier string    `db:"profile_identifier"`
+	ProfileName       string    `db:"profile_name"`
+	Checksum          []byte    `db:"checksum"`
+	InstalledAt       time.Time `db:"installed_at"`
 }
 
 // MDMAppleDeclaration represents a DDM JSON declaration.
@@ -0,0 +1,16 @@
+package fleet
+
+import "time"
+
+// HostMDMAppleActivationLockBypassCode is the Activation Lock bypass code
+// escrowed by a supervised Apple host. The code can be entered on the
+// Activation Lock screen of the host after it is erased, to set it up again
+// without the Apple Account that enabled Activation Lock.
+type HostMDMAppleActivationLockBypassCode struct {
+	HostID     uint   `json:"host_id" db:"host_id"`
+	BypassCode string `json:"activation_lock_bypass_code" db:"-"`
+	// ClearCommandUUID is the UUID of the pending
+	// ClearActivationLockBypassCode command, if any.
+	ClearCommandUUID *string   `json:"-" db:"clear_command_uuid"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
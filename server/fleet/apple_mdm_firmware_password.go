@@ -0,0 +1,20 @@
+package fleet
+
+import "time"
+
+// HostMDMAppleFirmwarePassword is the EFI firmware password set by Fleet on
+// an Intel Mac. The password is required to start the Mac from another
+// volume, and it takes effect when the Mac restarts after it is set.
+type HostMDMAppleFirmwarePassword struct {
+	HostID   uint   `json:"host_id" db:"host_id"`
+	HostUUID string `json:"-" db:"host_uuid"`
+	Password string `json:"firmware_password" db:"-"`
+	// Verified is nil until the host responds to the VerifyFirmwarePassword
+	// command sent after the password is set, false if the password didn't
+	// match.
+	Verified *bool `json:"verified" db:"verified"`
+	// SetCommandUUID is the UUID of the pending SetFirmwarePassword command,
+	// if any.
+	SetCommandUUID *string    `json:"-" db:"set_command_uuid"`
+	UpdatedAt      *time.Time `json:"updated_at" db:"password_updated_at"`
+}
@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"time"
+)
+
+// HostLostModeStatus is the status of the Lost Mode of an iOS or iPadOS host.
+type HostLostModeStatus string
+
+const (
+	// HostLostModeStatusDisabled is the status of a host that is not in Lost
+	// Mode.
+	HostLostModeStatusDisabled HostLostModeStatus = "disabled"
+	// HostLostModeStatusPendingEnable is the status of a host that was sent
+	// the command to enable Lost Mode, but did not acknowledge it yet.
+	HostLostModeStatusPendingEnable HostLostModeStatus = "pending_enable"
+	// HostLostModeStatusEnabled is the status of a host in Lost Mode.
+	HostLostModeStatusEnabled HostLostModeStatus = "enabled"
+	// HostLostModeStatusPendingDisable is the status of a host in Lost Mode
+	// that was sent the command to disable it, but did not acknowledge it yet.
+	HostLostModeStatusPendingDisable HostLostModeStatus = "pending_disable"
+)
+
+// HostLostModeSettings are the information displayed on the lock screen of a
+// host in Lost Mode.
+type HostLostModeSettings struct {
+	Message     string `json:"message" db:"message"`
+	PhoneNumber string `json:"phone_number" db:"phone_number"`
+	Footnote    string `json:"footnote" db:"footnote"`
+}
+
+// Validate returns an error if the settings are not valid, Apple requires
+// either a message or a phone number.
+func (s HostLostModeSettings) Validate() error {
+	if s.Message == "" && s.PhoneNumber == "" {
+		return NewInvalidArgumentError("message", "Either a message or a phone number is required to enable Lost Mode.")
+	}
+	return nil
+}
+
+// HostLostMode is the Lost Mode of an iOS or iPadOS host.
+type HostLostMode struct {
+	HostID uint               `json:"host_id" db:"host_id"`
+	Status HostLostModeStatus `json:"status" db:"status"`
+	HostLostModeSettings
+	EnableCommandUUID  string     `json:"-" db:"enable_command_uuid"`
+	DisableCommandUUID *string    `json:"-" db:"disable_command_uuid"`
+	UpdatedAt          *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HostMDMAppleLocation is the location reported by an iOS or iPadOS host in
+// Lost Mode in response to the DeviceLocation MDM command. The optional
+// attributes are nil if not reported by the host.
+type HostMDMAppleLocation struct {
+	HostID      uint    `json:"host_id" db:"host_id"`
+	CommandUUID string  `json:"-" db:"command_uuid"`
+	Latitude    float64 `json:"latitude" db:"latitude"`
+	Longitude   float64 `json:"longitude" db:"longitude"`
+	// Altitude is in meters.
+	Altitude *float64 `json:"altitude" db:"altitude"`
+	// HorizontalAccuracy and VerticalAccuracy are the radius of uncertainty,
+	// in meters.
+	HorizontalAccuracy *float64 `json:"horizontal_accuracy" db:"horizontal_accuracy"`
+	VerticalAccuracy   *float64 `json:"vertical_accuracy" db:"vertical_accuracy"`
+	// Speed is in meters per second.
+	Speed *float64 `json:"speed" db:"speed"`
+	// Course is the direction of travel, in degrees relative to due north.
+	Course *float64 `json:"course" db:"course"`
+	// ReportedAt is the time the host determined its location.
+	ReportedAt *time.Time `json:"reported_at" db:"reported_at"`
+	// CreatedAt is the time Fleet received the location.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,20 @@
+package fleet
+
+import "time"
+
+// HostMDMAppleRecoveryLockPassword is the Recovery Lock password set by Fleet
+// on an Apple silicon Mac. The password is required to start the Mac in
+// recoveryOS, and it is rotated periodically.
+type HostMDMAppleRecoveryLockPassword struct {
+	HostID   uint   `json:"host_id" db:"host_id"`
+	HostUUID string `json:"-" db:"host_uuid"`
+	Password string `json:"recovery_lock_password" db:"-"`
+	// Verified is nil until the host responds to the VerifyRecoveryLock
+	// command sent after the password is set, false if the password didn't
+	// match.
+	Verified *bool `json:"verified" db:"verified"`
+	// SetCommandUUID is the UUID of the pending SetRecoveryLock command, if
+	// any.
+	SetCommandUUID *string    `json:"-" db:"set_command_uuid"`
+	UpdatedAt      *time.Time `json:"updated_at" db:"password_updated_at"`
+}
@@ -416,3 +416,78 @@ func TestMDMProfileIsWithinGracePeriod(t *testing.T) {
 		})
 	}
 }
+
+func TestMDMAppleUserEnrollmentCommandRestriction(t *testing.T) {
+	cases := []struct {
+		requestType     string
+		pin             string
+		managedAppsOnly bool
+		want            string
+	}{
+		{"EraseDevice", "", false, MDMAppleUserEnrollmentEraseMessage},
+		{"EraseDevice", "123456", false, MDMAppleUserEnrollmentEraseMessage},
+		{"DeviceLock", "123456", false, MDMAppleUserEnrollmentLockPINMessage},
+		{"DeviceLock", "", false, ""},
+		{"InstalledApplicationList", "", false, MDMAppleUserEnrollmentAppListMessage},
+		{"InstalledApplicationList", "", true, ""},
+		{"DeviceInformation", "", false, ""},
+		{"ProfileList", "", false, ""},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s pin=%q managed=%t", c.requestType, c.pin, c.managedAppsOnly), func(t *testing.T) {
+			require.Equal(t, c.want, MDMAppleUserEnrollmentCommandRestriction(c.requestType, c.pin, c.managedAppsOnly))
+		})
+	}
+
+	for _, typ := range []string{"Device", "User", "Shared iPad"} {
+		require.False(t, (&NanoEnrollment{Type: typ}).IsUserEnrollment(), typ)
+	}
+	for _, typ := range []string{NanoEnrollmentTypeUserEnrollmentDevice, NanoEnrollmentTypeUserEnrollment} {
+		require.True(t, (&NanoEnrollment{Type: typ}).IsUserEnrollment(), typ)
+	}
+}
+
+func TestMDMAppleEraseDeviceOptions(t *testing.T) {
+	cases := []struct {
+		opts    *MDMAppleEraseDeviceOptions
+		wantErr string
+	}{
+		{nil, ""},
+		{&MDMAppleEraseDeviceOptions{}, ""},
+		{&MDMAppleEraseDeviceOptions{ObliterationBehavior: MDMAppleObliterationBehaviorAlways, PINLength: 4}, ""},
+		{&MDMAppleEraseDeviceOptions{ObliterationBehavior: MDMAppleObliterationBehaviorObliterateWithWarning, SkipPIN: true}, ""},
+		{&MDMAppleEraseDeviceOptions{ObliterationBehavior: "Never"}, "obliteration_behavior must be one of"},
+		{&MDMAppleEraseDeviceOptions{PINLength: 3}, "pin_length must be between 4 and 6"},
+		{&MDMAppleEraseDeviceOptions{PINLength: 7}, "pin_length must be between 4 and 6"},
+		{&MDMAppleEraseDeviceOptions{PINLength: 6, SkipPIN: true}, "pin_length can't be set when skip_pin is true"},
+	}
+	for _, c := range cases {
+		err := c.opts.Validate()
+		if c.wantErr == "" {
+			require.NoError(t, err)
+		} else {
+			require.ErrorContains(t, err, c.wantErr)
+		}
+	}
+
+	var settings MacOSSettings
+	set, err := settings.FromMap(map[string]interface{}{
+		"erase_device": map[string]interface{}{"obliteration_behavior": "DoNotObliterate", "skip_pin": true},
+	})
+	require.NoError(t, err)
+	require.True(t, set["erase_device"])
+	require.Equal(t, &MDMAppleEraseDeviceOptions{ObliterationBehavior: "DoNotObliterate", SkipPIN: true}, settings.EraseDevice)
+
+	// the options are kept if the key is not provided
+	_, err = settings.FromMap(map[string]interface{}{"custom_settings": nil})
+	require.NoError(t, err)
+	require.NotNil(t, settings.EraseDevice)
+
+	// and cleared with null
+	_, err = settings.FromMap(map[string]interface{}{"erase_device": nil})
+	require.NoError(t, err)
+	require.Nil(t, settings.EraseDevice)
+
+	_, err = settings.FromMap(map[string]interface{}{"erase_device": map[string]interface{}{"pin": "123456"}})
+	require.ErrorContains(t, err, `macos_settings.erase_device: json: unknown field "pin"`)
+}
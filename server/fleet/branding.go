@@ -0,0 +1,58 @@
+package fleet
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var brandingColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ValidateBranding validates the branding settings of the organization info,
+// errors are added to invalid with keys prefixed by prefix (e.g.
+// "org_info").
+func (o OrgInfo) ValidateBranding(prefix string, invalid *InvalidArgumentError) {
+	colors := []struct{ name, value string }{
+		{"primary_color", o.PrimaryColor},
+		{"background_color", o.BackgroundColor},
+	}
+	for _, c := range colors {
+		if c.value != "" && !brandingColorRegexp.MatchString(c.value) {
+			invalid.Append(prefix+"."+c.name, fmt.Sprintf("invalid color %q, must be a hex color such as #192147", c.value))
+		}
+	}
+}
+
+// ResolveBranding returns the branding of the end-user facing pages and apps
+// (enrollment pages, Fleet Desktop) for hosts of the team (nil for hosts with
+// no team). The branding settings set on the team override the settings of
+// the organization.
+func ResolveBranding(appConfig *AppConfig, team *Team) OrgInfo {
+	var branding OrgInfo
+	if appConfig != nil {
+		branding = appConfig.OrgInfo
+	}
+	if team == nil {
+		return branding
+	}
+
+	override := team.Config.Branding
+	if override.OrgName != "" {
+		branding.OrgName = override.OrgName
+	}
+	if override.OrgLogoURL != "" {
+		branding.OrgLogoURL = override.OrgLogoURL
+	}
+	if override.OrgLogoURLLightBackground != "" {
+		branding.OrgLogoURLLightBackground = override.OrgLogoURLLightBackground
+	}
+	if override.ContactURL != "" {
+		branding.ContactURL = override.ContactURL
+	}
+	if override.PrimaryColor != "" {
+		branding.PrimaryColor = override.PrimaryColor
+	}
+	if override.BackgroundColor != "" {
+		branding.BackgroundColor = override.BackgroundColor
+	}
+	return branding
+}
@@ -0,0 +1,44 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBranding(t *testing.T) {
+	require.Equal(t, OrgInfo{}, ResolveBranding(nil, nil))
+
+	appConfig := &AppConfig{OrgInfo: OrgInfo{
+		OrgName:      "Acme",
+		OrgLogoURL:   "https://acme.example/logo.png",
+		ContactURL:   "https://acme.example/help",
+		PrimaryColor: "#112233",
+	}}
+	require.Equal(t, appConfig.OrgInfo, ResolveBranding(appConfig, nil))
+	require.Equal(t, appConfig.OrgInfo, ResolveBranding(appConfig, &Team{}))
+
+	team := &Team{Config: TeamConfig{Branding: OrgInfo{
+		OrgName:         "Acme EMEA",
+		BackgroundColor: "#fafafa",
+	}}}
+	require.Equal(t, OrgInfo{
+		OrgName:         "Acme EMEA",
+		OrgLogoURL:      "https://acme.example/logo.png",
+		ContactURL:      "https://acme.example/help",
+		PrimaryColor:    "#112233",
+		BackgroundColor: "#fafafa",
+	}, ResolveBranding(appConfig, team))
+}
+
+func TestOrgInfoValidateBranding(t *testing.T) {
+	invalid := &InvalidArgumentError{}
+	OrgInfo{}.ValidateBranding("branding", invalid)
+	OrgInfo{PrimaryColor: "#192147", BackgroundColor: "#FFF"}.ValidateBranding("branding", invalid)
+	require.False(t, invalid.HasErrors())
+
+	OrgInfo{PrimaryColor: "red", BackgroundColor: "#12345"}.ValidateBranding("branding", invalid)
+	require.Len(t, invalid.Invalid(), 2)
+	require.Equal(t, "branding.primary_color", invalid.Invalid()[0]["name"])
+	require.Equal(t, "branding.background_color", invalid.Invalid()[1]["name"])
+}
@@ -19,6 +19,9 @@ type DistributedQueryCampaign struct {
 	QueryID uint                   `json:"query_id" db:"query_id"`
 	Status  DistributedQueryStatus `json:"status"`
 	UserID  uint                   `json:"user_id" db:"user_id"`
+	// PostProcessing is the post-processing applied to the results of the
+	// campaign, nil if the raw rows of the results are streamed.
+	PostProcessing *QueryPostProcessing `json:"post_processing,omitempty" db:"post_processing"`
 }
 
 // DistributedQueryCampaignTarget stores a target (host or label) for a
@@ -48,8 +51,27 @@ type DistributedQueryResult struct {
 	// Note we can't use the error interface here because something
 	// implementing that interface may not (un)marshal properly
 	Error *string `json:"error,omitempty"`
+	// Cursor is the position of the result in the campaign's results buffer,
+	// it is used by clients to resume reading the results of a campaign. It
+	// is 0 if the result is not buffered.
+	Cursor uint64 `json:"cursor,omitempty"`
 }
 
+// LiveQueryStreamOptions are the options of a stream of live query results,
+// sent by the client when it selects the campaign to read.
+type LiveQueryStreamOptions struct {
+	// Version is the version of the streaming protocol, 1 (the default) or 2.
+	// Version 2 supports resuming a campaign and stopping it explicitly.
+	Version int `json:"version"`
+	// Cursor is the cursor of the last result received by the client, to
+	// resume reading the results of the campaign (version 2 only).
+	Cursor uint64 `json:"cursor"`
+}
+
+// LiveQueryStreamVersion2 is the version of the live query streaming protocol
+// that supports resuming campaigns.
+const LiveQueryStreamVersion2 = 2
+
 // ResultHostData holds the host's data from where a query result comes from.
 type ResultHostData struct {
 	// ID is the unique ID of the host.
@@ -0,0 +1,144 @@
+package fleet
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeStreamResource is a resource whose changes can be streamed.
+type ChangeStreamResource string
+
+const (
+	ChangeStreamResourceHosts      ChangeStreamResource = "hosts"
+	ChangeStreamResourceActivities ChangeStreamResource = "activities"
+)
+
+// IsValid returns true if r is a supported resource.
+func (r ChangeStreamResource) IsValid() bool {
+	switch r {
+	case ChangeStreamResourceHosts, ChangeStreamResourceActivities:
+		return true
+	default:
+		return false
+	}
+}
+
+// ChangeEventType is the type of an event of the changes stream, sent as the
+// event name of the server-sent events.
+type ChangeEventType string
+
+const (
+	// ChangeEventHostUpdated is sent when a host is enrolled or updated.
+	ChangeEventHostUpdated ChangeEventType = "host_updated"
+	// ChangeEventActivityCreated is sent when an activity is created.
+	ChangeEventActivityCreated ChangeEventType = "activity_created"
+)
+
+// ChangeStreamCursor is the position of a client in the changes stream. It is
+// sent as the ID of each event, so that a client can resume the stream after
+// the last event it received.
+type ChangeStreamCursor struct {
+	// ActivityID is the ID of the last activity streamed.
+	ActivityID uint
+	// HostUpdatedAt and HostID are the update timestamp and ID of the last
+	// host streamed, the hosts are streamed in (updated_at, id) order.
+	HostUpdatedAt time.Time
+	HostID        uint
+}
+
+const changeStreamCursorVersion = "v1"
+
+// String returns the opaque representation of the cursor.
+func (c ChangeStreamCursor) String() string {
+	s := strings.Join([]string{
+		changeStreamCursorVersion,
+		strconv.FormatUint(uint64(c.ActivityID), 10),
+		strconv.FormatInt(c.HostUpdatedAt.Unix(), 10),
+		strconv.FormatUint(uint64(c.HostID), 10),
+	}, ":")
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// ParseChangeStreamCursor parses a cursor returned by
+// ChangeStreamCursor.String.
+func ParseChangeStreamCursor(s string) (ChangeStreamCursor, error) {
+	var c ChangeStreamCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor encoding")
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 4 || parts[0] != changeStreamCursorVersion {
+		return c, errors.New("invalid cursor format")
+	}
+
+	activityID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor activity ID: %w", err)
+	}
+	updatedAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor host timestamp: %w", err)
+	}
+	hostID, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor host ID: %w", err)
+	}
+
+	c.ActivityID = uint(activityID)
+	c.HostUpdatedAt = time.Unix(updatedAt, 0).UTC()
+	c.HostID = uint(hostID)
+	return c, nil
+}
+
+// HostChange is the state of a host sent in a host_updated event of the
+// changes stream.
+type HostChange struct {
+	ID             uint      `json:"id" db:"id"`
+	Hostname       string    `json:"hostname" db:"hostname"`
+	DisplayName    string    `json:"display_name" db:"display_name"`
+	Platform       string    `json:"platform" db:"platform"`
+	OSVersion      string    `json:"os_version" db:"os_version"`
+	OsqueryVersion string    `json:"osquery_version" db:"osquery_version"`
+	TeamID         *uint     `json:"team_id" db:"team_id"`
+	TeamName       *string   `json:"team_name" db:"team_name"`
+	SeenTime       time.Time `json:"seen_time" db:"seen_time"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ChangeEvent is an event of the changes stream.
+type ChangeEvent struct {
+	Type ChangeEventType
+	// Cursor is the position of the stream after this event.
+	Cursor ChangeStreamCursor
+	// Data is the *HostChange or *Activity of the event.
+	Data interface{}
+}
+
+// ChangeStreamOptions are the options to list the changes of the stream.
+type ChangeStreamOptions struct {
+	// Resources are the resources whose changes are streamed, all the
+	// resources if empty.
+	Resources []ChangeStreamResource
+	// Cursor is the position to resume the stream from. If nil, the stream
+	// starts from the current time.
+	Cursor *ChangeStreamCursor
+}
+
+// Includes returns true if the changes of the resource are streamed.
+func (o ChangeStreamOptions) Includes(r ChangeStreamResource) bool {
+	if len(o.Resources) == 0 {
+		return true
+	}
+	for _, res := range o.Resources {
+		if res == r {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package fleet
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeStreamCursor(t *testing.T) {
+	c := ChangeStreamCursor{ActivityID: 42, HostUpdatedAt: time.Date(2024, 6, 4, 9, 0, 0, 0, time.UTC), HostID: 7}
+	parsed, err := ParseChangeStreamCursor(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	for _, s := range []string{
+		"",
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("v2:1:2:3")),
+		base64.RawURLEncoding.EncodeToString([]byte("v1:1:2")),
+		base64.RawURLEncoding.EncodeToString([]byte("v1:a:2:3")),
+		base64.RawURLEncoding.EncodeToString([]byte("v1:1:-:3")),
+		base64.RawURLEncoding.EncodeToString([]byte("v1:1:2:-3")),
+	} {
+		_, err := ParseChangeStreamCursor(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestChangeStreamOptionsIncludes(t *testing.T) {
+	require.True(t, ChangeStreamOptions{}.Includes(ChangeStreamResourceHosts))
+	require.True(t, ChangeStreamOptions{}.Includes(ChangeStreamResourceActivities))
+
+	opts := ChangeStreamOptions{Resources: []ChangeStreamResource{ChangeStreamResourceHosts}}
+	require.True(t, opts.Includes(ChangeStreamResourceHosts))
+	require.False(t, opts.Includes(ChangeStreamResourceActivities))
+}
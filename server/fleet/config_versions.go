@@ -0,0 +1,174 @@
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ConfigVersion is a snapshot of the global configuration (if TeamID is nil)
+// or of a team's configuration, recorded every time it changes. It includes
+// the custom configuration profiles of the team (of "No team" for the global
+// configuration) at that time.
+type ConfigVersion struct {
+	ID uint `json:"id" db:"id"`
+	// TeamID is nil for the global configuration.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// AuthorID is the ID of the user that made the change, nil if the change
+	// was made by Fleet itself or if the user was deleted.
+	AuthorID   *uint     `json:"author_id" db:"author_id"`
+	AuthorName string    `json:"author_name" db:"author_name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	// Config is the AppConfig (for the global configuration) or the TeamConfig
+	// as JSON. It is not loaded when listing versions. Credentials are
+	// obfuscated when it is returned by the API.
+	Config json.RawMessage `json:"config,omitempty" db:"config"`
+	// Profiles are the custom configuration profiles of the team at that time.
+	// It is not loaded when listing versions.
+	Profiles []ConfigVersionProfile `json:"profiles,omitempty" db:"-"`
+}
+
+// ConfigVersionProfile is a configuration profile recorded in a
+// ConfigVersion. The contents of the profile are not returned by the API.
+type ConfigVersionProfile struct {
+	Name             string   `json:"name"`
+	Platform         string   `json:"platform"`
+	Labels           []string `json:"labels,omitempty"`
+	LabelsIncludeAny []string `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []string `json:"labels_exclude_any,omitempty"`
+	Contents         []byte   `json:"-"`
+	// Rollout is the staged rollout settings of the profile, nil if it has
+	// none.
+	Rollout *MDMProfileRolloutSettings `json:"rollout,omitempty"`
+}
+
+// ConfigVersionDiff describes the changes between two versions of the same
+// configuration.
+type ConfigVersionDiff struct {
+	FromVersionID uint `json:"from_version_id"`
+	ToVersionID   uint `json:"to_version_id"`
+	// Changes lists the settings that differ between the two versions, sorted
+	// by path.
+	Changes []ConfigVersionChange `json:"changes"`
+	// ProfilesAdded, ProfilesRemoved and ProfilesModified are the names of the
+	// configuration profiles that differ between the two versions.
+	ProfilesAdded    []string `json:"profiles_added"`
+	ProfilesRemoved  []string `json:"profiles_removed"`
+	ProfilesModified []string `json:"profiles_modified"`
+}
+
+// ConfigVersionChange is a setting that differs between two versions of a
+// configuration. Path is the dot-separated path of the setting in the
+// configuration (e.g. "server_settings.enable_analytics"), Old is nil if the
+// setting was added and New is nil if it was removed.
+type ConfigVersionChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// DiffConfigVersions returns the changes from version from to version to,
+// which must be versions of the same configuration.
+func DiffConfigVersions(from, to *ConfigVersion) (*ConfigVersionDiff, error) {
+	var fromCfg, toCfg any
+	if err := json.Unmarshal(from.Config, &fromCfg); err != nil {
+		return nil, fmt.Errorf("unmarshal config of version %d: %w", from.ID, err)
+	}
+	if err := json.Unmarshal(to.Config, &toCfg); err != nil {
+		return nil, fmt.Errorf("unmarshal config of version %d: %w", to.ID, err)
+	}
+
+	diff := &ConfigVersionDiff{
+		FromVersionID:    from.ID,
+		ToVersionID:      to.ID,
+		Changes:          []ConfigVersionChange{},
+		ProfilesAdded:    []string{},
+		ProfilesRemoved:  []string{},
+		ProfilesModified: []string{},
+	}
+	diffConfigValues("", fromCfg, toCfg, &diff.Changes)
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Path < diff.Changes[j].Path
+	})
+
+	fromProfs := make(map[string]ConfigVersionProfile, len(from.Profiles))
+	for _, p := range from.Profiles {
+		fromProfs[p.Name] = p
+	}
+	for _, p := range to.Profiles {
+		old, ok := fromProfs[p.Name]
+		switch {
+		case !ok:
+			diff.ProfilesAdded = append(diff.ProfilesAdded, p.Name)
+		case !bytes.Equal(old.Contents, p.Contents) || !equalStringSets(old.Labels, p.Labels) ||
+			!equalStringSets(old.LabelsIncludeAny, p.LabelsIncludeAny) || !equalStringSets(old.LabelsExcludeAny, p.LabelsExcludeAny) ||
+			!equalRolloutSettings(old.Rollout, p.Rollout):
+			diff.ProfilesModified = append(diff.ProfilesModified, p.Name)
+		}
+		delete(fromProfs, p.Name)
+	}
+	for name := range fromProfs {
+		diff.ProfilesRemoved = append(diff.ProfilesRemoved, name)
+	}
+	sort.Strings(diff.ProfilesAdded)
+	sort.Strings(diff.ProfilesRemoved)
+	sort.Strings(diff.ProfilesModified)
+
+	return diff, nil
+}
+
+// diffConfigValues appends to changes the differences between the decoded
+// JSON values from and to. Objects are compared key by key, any other value
+// (including arrays) is compared as a whole.
+func diffConfigValues(path string, from, to any, changes *[]ConfigVersionChange) {
+	fromObj, fromIsObj := from.(map[string]any)
+	toObj, toIsObj := to.(map[string]any)
+	if fromIsObj && toIsObj {
+		for k, fv := range fromObj {
+			diffConfigValues(joinConfigPath(path, k), fv, toObj[k], changes)
+		}
+		for k, tv := range toObj {
+			if _, ok := fromObj[k]; !ok {
+				diffConfigValues(joinConfigPath(path, k), nil, tv, changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, ConfigVersionChange{Path: path, Old: from, New: to})
+	}
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		set[s] = struct{}{}
+	}
+	for _, s := range b {
+		if _, ok := set[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func equalRolloutSettings(a, b *MDMProfileRolloutSettings) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
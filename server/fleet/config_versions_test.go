@@ -0,0 +1,60 @@
+package fleet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigVersions(t *testing.T) {
+	from := &ConfigVersion{
+		ID:     1,
+		Config: json.RawMessage(`{"org_info": {"org_name": "Fleet", "org_logo_url": ""}, "features": {"enable_host_users": true}, "host_expiry_settings": {"host_expiry_window": 30}, "removed": 1, "labels": ["a", "b"]}`),
+		Profiles: []ConfigVersionProfile{
+			{Name: "same", Platform: "darwin", Labels: []string{"l1", "l2"}, Contents: []byte("same")},
+			{Name: "contents", Platform: "darwin", Contents: []byte("old")},
+			{Name: "labels", Platform: "windows", Labels: []string{"l1"}, Contents: []byte("labels")},
+			{Name: "removed", Platform: "windows", Contents: []byte("removed")},
+			{Name: "rollout", Platform: "darwin", Contents: []byte("rollout"), Rollout: &MDMProfileRolloutSettings{CanaryPercentage: 10}},
+		},
+	}
+	to := &ConfigVersion{
+		ID:     2,
+		Config: json.RawMessage(`{"org_info": {"org_name": "Acme", "org_logo_url": ""}, "features": {"enable_host_users": true, "enable_software_inventory": false}, "host_expiry_settings": null, "labels": ["b", "a"]}`),
+		Profiles: []ConfigVersionProfile{
+			{Name: "added", Platform: "darwin", Contents: []byte("added")},
+			{Name: "same", Platform: "darwin", Labels: []string{"l2", "l1"}, Contents: []byte("same")},
+			{Name: "contents", Platform: "darwin", Contents: []byte("new")},
+			{Name: "labels", Platform: "windows", Labels: []string{"l2"}, Contents: []byte("labels")},
+			{Name: "rollout", Platform: "darwin", Contents: []byte("rollout"), Rollout: &MDMProfileRolloutSettings{CanaryPercentage: 20}},
+		},
+	}
+
+	diff, err := DiffConfigVersions(from, to)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), diff.FromVersionID)
+	require.Equal(t, uint(2), diff.ToVersionID)
+	require.Equal(t, []ConfigVersionChange{
+		{Path: "features.enable_software_inventory", Old: nil, New: false},
+		{Path: "host_expiry_settings", Old: map[string]any{"host_expiry_window": float64(30)}, New: nil},
+		{Path: "labels", Old: []any{"a", "b"}, New: []any{"b", "a"}},
+		{Path: "org_info.org_name", Old: "Fleet", New: "Acme"},
+		{Path: "removed", Old: float64(1), New: nil},
+	}, diff.Changes)
+	require.Equal(t, []string{"added"}, diff.ProfilesAdded)
+	require.Equal(t, []string{"removed"}, diff.ProfilesRemoved)
+	require.Equal(t, []string{"contents", "labels", "rollout"}, diff.ProfilesModified)
+
+	// no changes
+	diff, err = DiffConfigVersions(from, from)
+	require.NoError(t, err)
+	require.Empty(t, diff.Changes)
+	require.Empty(t, diff.ProfilesAdded)
+	require.Empty(t, diff.ProfilesRemoved)
+	require.Empty(t, diff.ProfilesModified)
+
+	// invalid config
+	_, err = DiffConfigVersions(from, &ConfigVersion{ID: 3, Config: json.RawMessage(`{`)})
+	require.ErrorContains(t, err, "unmarshal config of version 3")
+}
@@ -1,6 +1,9 @@
 package fleet
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -12,21 +15,36 @@ type CronScheduleName string
 
 // List of recognized cron schedule names.
 const (
-	CronAppleMDMDEPProfileAssigner CronScheduleName = "apple_mdm_dep_profile_assigner"
-	CronCleanupsThenAggregation    CronScheduleName = "cleanups_then_aggregation"
-	CronFrequentCleanups           CronScheduleName = "frequent_cleanups"
-	CronUsageStatistics            CronScheduleName = "usage_statistics"
-	CronVulnerabilities            CronScheduleName = "vulnerabilities"
-	CronAutomations                CronScheduleName = "automations"
-	CronWorkerIntegrations         CronScheduleName = "integrations"
-	CronActivitiesStreaming        CronScheduleName = "activities_streaming"
-	CronMDMAppleProfileManager     CronScheduleName = "mdm_apple_profile_manager"
-	CronCalendar                   CronScheduleName = "calendar"
+	CronAppleMDMDEPProfileAssigner    CronScheduleName = "apple_mdm_dep_profile_assigner"
+	CronCleanupsThenAggregation       CronScheduleName = "cleanups_then_aggregation"
+	CronFrequentCleanups              CronScheduleName = "frequent_cleanups"
+	CronUsageStatistics               CronScheduleName = "usage_statistics"
+	CronVulnerabilities               CronScheduleName = "vulnerabilities"
+	CronAutomations                   CronScheduleName = "automations"
+	CronWorkerIntegrations            CronScheduleName = "integrations"
+	CronActivitiesStreaming           CronScheduleName = "activities_streaming"
+	CronMDMAppleProfileManager        CronScheduleName = "mdm_apple_profile_manager"
+	CronCalendar                      CronScheduleName = "calendar"
+	CronScriptSchedules               CronScheduleName = "script_schedules"
+	CronMergeDuplicateHosts           CronScheduleName = "merge_duplicate_hosts"
+	CronDataExports                   CronScheduleName = "data_exports"
+	CronAppleMDMIPhoneIPadRefetcher   CronScheduleName = "apple_mdm_iphone_ipad_refetcher"
+	CronAppleMDMSoftwareRefetcher     CronScheduleName = "apple_mdm_software_refetcher"
+	CronAppleMDMNotNowRetrier         CronScheduleName = "apple_mdm_not_now_retrier"
+	CronMDMProfileRedeliveryCampaigns CronScheduleName = "mdm_profile_redelivery_campaigns"
+	CronMDMProfileRollouts            CronScheduleName = "mdm_profile_rollouts"
+	CronHostWarranties                CronScheduleName = "host_warranties"
+	CronHostHealthScores              CronScheduleName = "host_health_scores"
+	CronDatabaseMaintenance           CronScheduleName = "database_maintenance"
+	CronAdminNotifications            CronScheduleName = "admin_notifications"
 )
 
 type CronSchedulesService interface {
 	// TriggerCronSchedule attempts to trigger an ad-hoc run of the named cron schedule.
 	TriggerCronSchedule(name string) error
+	// ScheduleIntervals returns the current interval of each cron schedule
+	// registered with the service, by name.
+	ScheduleIntervals() map[string]time.Duration
 }
 
 func NewCronSchedules() *CronSchedules {
@@ -36,6 +54,7 @@ func NewCronSchedules() *CronSchedules {
 type CronSchedule interface {
 	Trigger() (*CronStats, error)
 	Name() string
+	Interval() time.Duration
 	Start()
 }
 
@@ -78,6 +97,16 @@ func (cs *CronSchedules) TriggerCronSchedule(name string) error {
 	}
 }
 
+// ScheduleIntervals returns the current interval of each cron schedule
+// registered with the service, by name.
+func (cs *CronSchedules) ScheduleIntervals() map[string]time.Duration {
+	res := make(map[string]time.Duration, len(cs.Schedules))
+	for name, sched := range cs.Schedules {
+		res[name] = sched.Interval()
+	}
+	return res
+}
+
 // ScheduleNames returns a list of the names of all cron schedules registered with the service.
 func (cs *CronSchedules) ScheduleNames() []string {
 	var res []string
@@ -162,6 +191,35 @@ type CronStats struct {
 	// Status is the current status of the run. Recognized statuses are "pending", "completed", and
 	// "expired".
 	Status CronStatsStatus `db:"status"`
+	// Errors are the errors of the jobs that failed during the run, by job ID.
+	Errors CronScheduleErrors `db:"errors"`
+}
+
+// CronScheduleErrors are the errors of the jobs of a cron schedule run, by
+// job ID. They are stored as a JSON object.
+type CronScheduleErrors map[string]string
+
+// Scan implements the sql.Scanner interface.
+func (e *CronScheduleErrors) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, e)
+	case string:
+		return json.Unmarshal([]byte(src), e)
+	default:
+		return errors.New("unsupported type for cron schedule errors")
+	}
+}
+
+// Value implements the driver.Valuer interface.
+func (e CronScheduleErrors) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(e)
 }
 
 // CronStatsType is one of two recognized types of cron stats (i.e. "scheduled" or "triggered")
@@ -183,3 +241,54 @@ const (
 	CronStatsStatusCompleted CronStatsStatus = "completed"
 	CronStatsStatusCanceled  CronStatsStatus = "canceled"
 )
+
+// CronScheduleStatus is the status of a cron schedule, as returned by the
+// crons API.
+type CronScheduleStatus struct {
+	// Name is the name of the schedule.
+	Name string `json:"name"`
+	// Interval is the interval between the scheduled runs.
+	Interval Duration `json:"interval"`
+	// Paused is true if the scheduled runs are paused, the schedule can
+	// still be triggered.
+	Paused bool `json:"paused"`
+	// CurrentRun is the run in progress, if any.
+	CurrentRun *CronScheduleRun `json:"current_run"`
+	// LastRun is the last finished run, if any.
+	LastRun *CronScheduleRun `json:"last_run"`
+	// NextRunAt is the expected start of the next scheduled run, nil if the
+	// schedule is paused.
+	NextRunAt *time.Time `json:"next_run_at"`
+}
+
+// CronScheduleRun is a run of a cron schedule.
+type CronScheduleRun struct {
+	Type      CronStatsType   `json:"type"`
+	Status    CronStatsStatus `json:"status"`
+	Instance  string          `json:"instance"`
+	StartedAt time.Time       `json:"started_at"`
+	// FinishedAt and DurationSeconds are only set for finished runs.
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	DurationSeconds *float64   `json:"duration_seconds,omitempty"`
+	// Errors are the errors of the jobs that failed during the run, by job
+	// ID.
+	Errors CronScheduleErrors `json:"errors,omitempty"`
+}
+
+// NewCronScheduleRun returns the run of the stats.
+func NewCronScheduleRun(stats CronStats) *CronScheduleRun {
+	run := &CronScheduleRun{
+		Type:      stats.StatsType,
+		Status:    stats.Status,
+		Instance:  stats.Instance,
+		StartedAt: stats.CreatedAt,
+		Errors:    stats.Errors,
+	}
+	if stats.Status != CronStatsStatusPending {
+		finishedAt := stats.UpdatedAt
+		duration := finishedAt.Sub(stats.CreatedAt).Seconds()
+		run.FinishedAt = &finishedAt
+		run.DurationSeconds = &duration
+	}
+	return run
+}
@@ -0,0 +1,114 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DataExportMaxSyncRows is the maximum number of rows of an export that is
+// generated synchronously, larger exports are generated in the background and
+// downloaded once completed.
+const DataExportMaxSyncRows = 5000
+
+// DataExportRetention is how long the exports generated in the background are
+// kept before being deleted.
+const DataExportRetention = 24 * time.Hour
+
+// DataExportFormat is the file format of an export.
+type DataExportFormat string
+
+const (
+	DataExportFormatCSV  DataExportFormat = "csv"
+	DataExportFormatXLSX DataExportFormat = "xlsx"
+)
+
+// IsValid returns true if the format is supported.
+func (f DataExportFormat) IsValid() bool {
+	return f == DataExportFormatCSV || f == DataExportFormatXLSX
+}
+
+// ContentType returns the MIME type of the export files of this format.
+func (f DataExportFormat) ContentType() string {
+	if f == DataExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// DataExportResource is the kind of resource exported.
+type DataExportResource string
+
+const (
+	DataExportResourceHosts           DataExportResource = "hosts"
+	DataExportResourceSoftware        DataExportResource = "software"
+	DataExportResourceVulnerabilities DataExportResource = "vulnerabilities"
+)
+
+// Title returns the human-readable name of the resource, used to name the
+// export file and its sheet.
+func (r DataExportResource) Title() string {
+	switch r {
+	case DataExportResourceHosts:
+		return "Hosts"
+	case DataExportResourceSoftware:
+		return "Software"
+	case DataExportResourceVulnerabilities:
+		return "Vulnerabilities"
+	default:
+		return string(r)
+	}
+}
+
+// DataExportStatus is the status of an export generated in the background.
+type DataExportStatus string
+
+const (
+	DataExportStatusPending   DataExportStatus = "pending"
+	DataExportStatusCompleted DataExportStatus = "completed"
+	DataExportStatusFailed    DataExportStatus = "failed"
+)
+
+// DataExport is a CSV or XLSX export of the hosts, software or
+// vulnerabilities matching a set of filters.
+type DataExport struct {
+	ID       uint               `json:"id" db:"id"`
+	UserID   uint               `json:"user_id" db:"user_id"`
+	Resource DataExportResource `json:"resource" db:"resource"`
+	Format   DataExportFormat   `json:"format" db:"format"`
+	Status   DataExportStatus   `json:"status" db:"status"`
+	Filename string             `json:"filename" db:"filename"`
+	// Size is the size in bytes of the generated file.
+	Size int64 `json:"size" db:"size"`
+	// ErrorMessage is the reason why the export failed.
+	ErrorMessage *string `json:"error_message" db:"error_message"`
+	// Options are the JSON-encoded filters of the export, e.g. a
+	// HostExportOptions for a hosts export.
+	Options     json.RawMessage `json:"-" db:"options"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at" db:"completed_at"`
+
+	// Data is the content of the generated file. It is only set when the file
+	// is downloaded or when it was generated synchronously.
+	Data []byte `json:"-" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (e *DataExport) AuthzType() string {
+	return "data_export"
+}
+
+// NewDataExportFilename returns the name of the file of an export created at
+// the given time.
+func NewDataExportFilename(resource DataExportResource, format DataExportFormat, createdAt time.Time) string {
+	return fmt.Sprintf("%s %s.%s", resource.Title(), createdAt.Format("2006-01-02"), format)
+}
+
+// HostExportOptions are the filters of a hosts export.
+type HostExportOptions struct {
+	ListOptions HostListOptions `json:"list_options"`
+	// LabelID restricts the export to the hosts that are members of the label.
+	LabelID *uint `json:"label_id"`
+	// Columns are the columns of the export, all columns if empty.
+	Columns []string `json:"columns"`
+}
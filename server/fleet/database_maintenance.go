@@ -0,0 +1,36 @@
+package fleet
+
+import "time"
+
+// DatabaseMaintenanceRun is the last run of a task of the database_maintenance
+// cron schedule.
+type DatabaseMaintenanceRun struct {
+	Name       string    `json:"name" db:"name"`
+	LastRunAt  time.Time `json:"last_run_at" db:"last_run_at"`
+	DurationMS uint      `json:"duration_ms" db:"duration_ms"`
+	// Error is the error of the last run, nil if it succeeded.
+	Error *string `json:"error" db:"error"`
+}
+
+// TableGrowth is the size of a database table, as estimated by MySQL when the
+// latest table size snapshot was taken, and its growth since the snapshots
+// taken a day and a week before. The growth is nil if there is no snapshot
+// old enough.
+type TableGrowth struct {
+	TableName  string    `json:"table_name" db:"table_name"`
+	Rows       int64     `json:"rows" db:"table_rows"`
+	DataBytes  int64     `json:"data_bytes" db:"data_bytes"`
+	IndexBytes int64     `json:"index_bytes" db:"index_bytes"`
+	FreeBytes  int64     `json:"free_bytes" db:"free_bytes"`
+	MeasuredAt time.Time `json:"measured_at" db:"created_at"`
+
+	RowsGrowth1d  *int64 `json:"rows_growth_1d"`
+	RowsGrowth7d  *int64 `json:"rows_growth_7d"`
+	BytesGrowth1d *int64 `json:"bytes_growth_1d"`
+	BytesGrowth7d *int64 `json:"bytes_growth_7d"`
+}
+
+// TotalBytes returns the size of the data and indexes of the table.
+func (t *TableGrowth) TotalBytes() int64 {
+	return t.DataBytes + t.IndexBytes
+}
@@ -95,7 +95,7 @@ type Datastore interface {
 	// ObserverCanRunQuery returns whether a user with an observer role is permitted to run the
 	// identified query
 	ObserverCanRunQuery(ctx context.Context, queryID uint) (bool, error)
-	// CleanupGlobalDiscardQueryResults deletes all cached query results. Used in cleanups_then_aggregation cron.
+	// CleanupGlobalDiscardQueryResults deletes all cached query results. Used in database_maintenance cron.
 	CleanupGlobalDiscardQueryResults(ctx context.Context) error
 	// IsSavedQuery returns true if the given query is a saved query.
 	IsSavedQuery(ctx context.Context, queryID uint) (bool, error)
@@ -227,6 +227,21 @@ type Datastore interface {
 	GetHostHealth(ctx context.Context, id uint) (*HostHealth, error)
 	ListHosts(ctx context.Context, filter TeamFilter, opt HostListOptions) ([]*Host, error)
 
+	// StreamHosts calls fn with successive batches of at most batchSize hosts
+	// matching the filter and options, sorted by ID. The hosts are loaded one
+	// batch at a time using keyset pagination on the host ID, so that all the
+	// hosts never have to be held in memory. The pagination and ordering
+	// options are ignored. Streaming stops and the error is returned if fn
+	// returns an error.
+	StreamHosts(ctx context.Context, filter TeamFilter, opt HostListOptions, batchSize int, fn func([]*Host) error) error
+
+	// ListHostChanges returns at most limit hosts visible to the filter that
+	// were updated after the host with the given update timestamp and ID,
+	// sorted by update timestamp and ID. The hosts updated during the current
+	// second are not returned yet, so that no host is missed when the stream
+	// is resumed from the last host returned.
+	ListHostChanges(ctx context.Context, filter TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*HostChange, error)
+
 	// ListHostsLiteByUUIDs returns the "lite" version of hosts corresponding to
 	// the provided uuids and filtered according to the provided team filters. It
 	// does include the MDMInfo information (unlike HostLite and
@@ -243,6 +258,12 @@ type Datastore interface {
 	ListHostsLiteByIDs(ctx context.Context, ids []uint) ([]*Host, error)
 
 	MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.Time) error
+	// MarkHostsSeenByChannel records that the hosts were seen at time t by
+	// the channel (e.g. orbit or Fleet Desktop).
+	MarkHostsSeenByChannel(ctx context.Context, channel HostSeenChannel, hostIDs []uint, t time.Time) error
+	// MarkHostMDMSeen records that the host identified by its UUID checked in
+	// with the MDM server at time t.
+	MarkHostMDMSeen(ctx context.Context, hostUUID string, t time.Time) error
 	SearchHosts(ctx context.Context, filter TeamFilter, query string, omit ...uint) ([]*Host, error)
 	// EnrolledHostIDs returns the full list of enrolled host IDs.
 	EnrolledHostIDs(ctx context.Context) ([]uint, error)
@@ -259,6 +280,9 @@ type Datastore interface {
 	GenerateHostStatusStatistics(ctx context.Context, filter TeamFilter, now time.Time, platform *string, lowDiskSpace *int) (*HostSummary, error)
 	// HostIDsByName Retrieve the IDs associated with the given hostnames
 	HostIDsByName(ctx context.Context, filter TeamFilter, hostnames []string) ([]uint, error)
+	// HostIDsByIdentifiers retrieves the IDs of the hosts matching any of the
+	// identifiers by hostname, UUID or hardware serial number.
+	HostIDsByIdentifiers(ctx context.Context, filter TeamFilter, identifiers []string) ([]uint, error)
 
 	// HostIDsByOSID retrieves the IDs of all host for the given OS ID
 	HostIDsByOSID(ctx context.Context, osID uint, offset int, limit int) ([]uint, error)
@@ -370,8 +394,9 @@ type Datastore interface {
 	// ListSessionsForUser finds all the active sessions for a given user
 	ListSessionsForUser(ctx context.Context, id uint) ([]*Session, error)
 
-	// NewSession stores a new session struct
-	NewSession(ctx context.Context, userID uint, sessionKey string) (*Session, error)
+	// NewSession stores a new session struct. The IP address and user agent
+	// identify the client that created the session.
+	NewSession(ctx context.Context, userID uint, sessionKey, ipAddress, userAgent string) (*Session, error)
 
 	// DestroySession destroys the currently tracked session
 	DestroySession(ctx context.Context, session *Session) error
@@ -382,6 +407,341 @@ type Datastore interface {
 	// MarkSessionAccessed marks the currently tracked session as access to extend expiration
 	MarkSessionAccessed(ctx context.Context, session *Session) error
 
+	///////////////////////////////////////////////////////////////////////////////
+	// APITokenStore contains methods for managing team-scoped API tokens.
+
+	// NewAPIToken stores a new API token with the provided hash of its secret.
+	NewAPIToken(ctx context.Context, token *APIToken, tokenHash string) (*APIToken, error)
+	// APIToken returns the API token identified by id.
+	APIToken(ctx context.Context, id uint) (*APIToken, error)
+	// APITokenByHash returns the API token corresponding to the hash of its
+	// secret, or a NotFoundError if there is none.
+	APITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	// ListAPITokens returns the API tokens of the provided team, or of all teams
+	// if teamID is nil.
+	ListAPITokens(ctx context.Context, teamID *uint) ([]*APIToken, error)
+	// DeleteAPIToken deletes the API token identified by id.
+	DeleteAPIToken(ctx context.Context, id uint) error
+	// MarkAPITokenUsed records the last time the API token was used.
+	MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// UserMFAStore contains methods for managing the second authentication
+	// factors of password-based users. The users.mfa_enabled flag is kept in
+	// sync by the methods that add or remove a factor.
+
+	// SetUserTOTPSecret stores a new, unconfirmed, TOTP secret for the user,
+	// replacing any existing one.
+	SetUserTOTPSecret(ctx context.Context, userID uint, secret string) error
+
+	// UserTOTPSecret returns the TOTP secret of the user.
+	UserTOTPSecret(ctx context.Context, userID uint) (*UserTOTPSecret, error)
+
+	// ConfirmUserTOTPSecret marks the TOTP secret of the user as confirmed with
+	// a code for the provided time step.
+	ConfirmUserTOTPSecret(ctx context.Context, userID uint, step int64) error
+
+	// MarkUserTOTPStepUsed records that the TOTP code of the time step was used
+	// to log in. It returns false if that step (or a later one) was already
+	// used.
+	MarkUserTOTPStepUsed(ctx context.Context, userID uint, step int64) (bool, error)
+
+	// DeleteUserTOTPSecret deletes the TOTP secret of the user.
+	DeleteUserTOTPSecret(ctx context.Context, userID uint) error
+
+	// NewUserWebAuthnCredential stores a new WebAuthn credential for the user.
+	NewUserWebAuthnCredential(ctx context.Context, cred *UserWebAuthnCredential) (*UserWebAuthnCredential, error)
+
+	// ListUserWebAuthnCredentials returns the WebAuthn credentials of the user.
+	ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*UserWebAuthnCredential, error)
+
+	// MarkUserWebAuthnCredentialUsed records the new signature counter of the
+	// credential after it was used to log in.
+	MarkUserWebAuthnCredentialUsed(ctx context.Context, id uint, signCount uint32) error
+
+	// DeleteUserWebAuthnCredential deletes a WebAuthn credential of the user.
+	DeleteUserWebAuthnCredential(ctx context.Context, userID, id uint) error
+
+	// ReplaceUserMFARecoveryCodes replaces the recovery codes of the user with
+	// the provided hashes.
+	ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+
+	// UseUserMFARecoveryCode marks the unused recovery code of the user with
+	// the provided hash as used. It returns a NotFoundError if there is no
+	// such code.
+	UseUserMFARecoveryCode(ctx context.Context, userID uint, codeHash string) error
+
+	// CountUserMFARecoveryCodes returns the number of unused recovery codes of
+	// the user.
+	CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error)
+
+	// DeleteUserMFA deletes all second factors and recovery codes of the user.
+	DeleteUserMFA(ctx context.Context, userID uint) error
+
+	// NewMFAChallenge stores a new pending MFA challenge.
+	NewMFAChallenge(ctx context.Context, challenge *MFAChallenge) error
+
+	// MFAChallenge returns the MFA challenge identified by the token.
+	MFAChallenge(ctx context.Context, token string) (*MFAChallenge, error)
+
+	// RecordMFAChallengeAttempt counts an attempt to complete the MFA
+	// challenge identified by the token. It returns false if the challenge
+	// already had maxAttempts attempts, in which case it must not be used.
+	RecordMFAChallengeAttempt(ctx context.Context, token string, maxAttempts int) (bool, error)
+
+	// DeleteMFAChallenge deletes the MFA challenge identified by the token.
+	DeleteMFAChallenge(ctx context.Context, token string) error
+
+	// CleanupExpiredMFAChallenges deletes the MFA challenges that have expired.
+	CleanupExpiredMFAChallenges(ctx context.Context) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// SecretVariableStore contains methods for managing secret variables. Values
+	// are encrypted with the server private key before being stored.
+
+	// NewSecretVariable stores a new secret variable. It returns an
+	// AlreadyExistsError if a secret variable with that name already exists.
+	NewSecretVariable(ctx context.Context, secret SecretVariablePayload) (*SecretVariable, error)
+
+	// UpsertSecretVariables creates the provided secret variables, or updates
+	// the value of those that already exist.
+	UpsertSecretVariables(ctx context.Context, secrets []SecretVariablePayload) error
+
+	// SecretVariable returns the secret variable identified by id, without its
+	// value.
+	SecretVariable(ctx context.Context, id uint) (*SecretVariable, error)
+
+	// ListSecretVariables returns all secret variables, without their values.
+	ListSecretVariables(ctx context.Context) ([]*SecretVariable, error)
+
+	// GetSecretVariables returns the secret variables with the provided names,
+	// including their decrypted values. Names that do not exist are ignored.
+	GetSecretVariables(ctx context.Context, names []string) ([]*SecretVariable, error)
+
+	// DeleteSecretVariable deletes the secret variable identified by id.
+	DeleteSecretVariable(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// ConfigVersionStore contains methods for managing the history of the
+	// global and team configurations.
+
+	// NewConfigVersion records a snapshot of the current global configuration
+	// (if teamID is nil) or team configuration, along with the custom
+	// configuration profiles of that team ("No team" for the global
+	// configuration). The author may be nil. If nothing changed since the latest
+	// version, no version is recorded and the latest version is returned.
+	NewConfigVersion(ctx context.Context, teamID *uint, author *User) (*ConfigVersion, error)
+
+	// ConfigVersion returns the configuration version identified by id,
+	// including its configuration and profiles.
+	ConfigVersion(ctx context.Context, id uint) (*ConfigVersion, error)
+
+	// ListConfigVersions returns the versions of the global configuration (if
+	// teamID is nil) or team configuration, without their configuration and
+	// profiles.
+	ListConfigVersions(ctx context.Context, teamID *uint, opt ListOptions) ([]*ConfigVersion, *PaginationMetadata, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// EmailTemplateStore contains methods for managing the customizations of
+	// the emails sent by Fleet.
+
+	// ListEmailTemplates returns the email templates that have been
+	// customized.
+	ListEmailTemplates(ctx context.Context) ([]*EmailTemplate, error)
+
+	// EmailTemplate returns the customization of the email template name. It
+	// returns a NotFoundError if the email template is not customized.
+	EmailTemplate(ctx context.Context, name EmailTemplateName) (*EmailTemplate, error)
+
+	// SaveEmailTemplate creates or replaces the customization of the email
+	// template.
+	SaveEmailTemplate(ctx context.Context, tmpl *EmailTemplate) (*EmailTemplate, error)
+
+	// DeleteEmailTemplate deletes the customization of the email template
+	// name, so that the default template is used. It does not fail if the
+	// email template is not customized.
+	DeleteEmailTemplate(ctx context.Context, name EmailTemplateName) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// TranslationStore contains methods for managing the translations of the
+	// end-user facing messages.
+
+	// ListTranslations returns the translations of all the locales.
+	ListTranslations(ctx context.Context) ([]*Translations, error)
+
+	// Translations returns the translations for the locale. It returns a
+	// NotFoundError if the locale has no translations.
+	Translations(ctx context.Context, locale string) (*Translations, error)
+
+	// ApplyTranslations creates or replaces the translations for the locale of
+	// t.
+	ApplyTranslations(ctx context.Context, t *Translations) (*Translations, error)
+
+	// DeleteTranslations deletes the translations for the locale. It returns a
+	// NotFoundError if the locale has no translations.
+	DeleteTranslations(ctx context.Context, locale string) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostGroupStore contains methods for managing host groups, static
+	// collections of hosts.
+
+	// NewHostGroup creates a new host group. It returns an AlreadyExistsError if
+	// a host group with that name already exists.
+	NewHostGroup(ctx context.Context, group *HostGroup) (*HostGroup, error)
+
+	// HostGroup returns the host group identified by id. Its host count only
+	// includes the hosts visible with the team filter.
+	HostGroup(ctx context.Context, filter TeamFilter, id uint) (*HostGroup, error)
+
+	// ListHostGroups returns the host groups, with host counts that only include
+	// the hosts visible with the team filter.
+	ListHostGroups(ctx context.Context, filter TeamFilter, opt ListOptions) ([]*HostGroup, error)
+
+	// SaveHostGroup updates the name and description of the host group.
+	SaveHostGroup(ctx context.Context, group *HostGroup) (*HostGroup, error)
+
+	// DeleteHostGroup deletes the host group identified by id, along with its
+	// memberships.
+	DeleteHostGroup(ctx context.Context, id uint) error
+
+	// AddHostsToHostGroup adds the hosts to the host group, hosts that are
+	// already members are ignored.
+	AddHostsToHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error
+
+	// RemoveHostsFromHostGroup removes the hosts from the host group.
+	RemoveHostsFromHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostCustomFieldsStore contains methods for the notes and custom fields of
+	// hosts.
+
+	// GetHostCustomFields returns the notes and custom field values of the
+	// host.
+	GetHostCustomFields(ctx context.Context, hostID uint) (*HostCustomFields, error)
+
+	// UpdateHostCustomFields updates the notes and custom field values of the
+	// host as described by the payload, which must have been validated.
+	UpdateHostCustomFields(ctx context.Context, hostID uint, payload HostCustomFieldsPayload) error
+
+	// DeleteHostCustomFieldValuesNotIn deletes the values of all custom fields
+	// whose name is not in names, i.e. fields that are not defined anymore.
+	DeleteHostCustomFieldValuesNotIn(ctx context.Context, names []string) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostWarrantyStore contains methods for the warranty information of hosts
+	// looked up from the vendors' APIs.
+
+	// ListHostsForWarrantyLookup returns up to limit hosts with a serial number
+	// made by one of the vendors and whose warranty was never looked up or was
+	// last looked up before checkedBefore. Only the ID, hardware vendor and
+	// hardware serial of the hosts are loaded.
+	ListHostsForWarrantyLookup(ctx context.Context, vendors []WarrantyVendor, checkedBefore time.Time, limit int) ([]*Host, error)
+
+	// UpsertHostWarranties creates or updates the warranty information of the
+	// hosts and records that they were just looked up.
+	UpsertHostWarranties(ctx context.Context, warranties []*HostWarranty) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostHealthScoreStore contains methods for the health scores of hosts.
+
+	// ListHostHealthScoreInputs returns the signals used to compute the health
+	// score of up to limit hosts with an ID greater than afterHostID, ordered by
+	// host ID. Only the fleetd service recoveries since recoveredSince are
+	// counted.
+	ListHostHealthScoreInputs(ctx context.Context, afterHostID uint, recoveredSince time.Time, limit int) ([]*HostHealthScoreInputs, error)
+
+	// UpsertHostHealthScores creates or updates the health scores of the hosts.
+	UpsertHostHealthScores(ctx context.Context, scores []*HostHealthScore) error
+
+	// GetHostHealthScores returns the health scores of the hosts, indexed by
+	// host ID. Hosts whose score was not computed yet are not in the map.
+	GetHostHealthScores(ctx context.Context, hostIDs []uint) (map[uint]*HostHealthScore, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostPendingApprovalStore contains methods for the hosts that are pending
+	// enrollment approval.
+
+	// ListHostsPendingApproval returns the hosts visible with the team filter
+	// that are pending enrollment approval, oldest enrollments first.
+	ListHostsPendingApproval(ctx context.Context, filter TeamFilter) ([]*HostPendingApproval, error)
+
+	// ApproveHostEnrollment approves the enrollment of the host, which then
+	// receives its configuration profiles, scripts and policies. It returns a
+	// NotFoundError if the host is not pending approval.
+	ApproveHostEnrollment(ctx context.Context, hostID uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// DuplicateHostStore contains methods for the hosts that were enrolled more
+	// than once.
+
+	// ListDuplicateHosts returns the hosts visible with the team filter that
+	// have the same hardware serial number or hardware UUID as another host,
+	// grouped in sets of duplicates.
+	ListDuplicateHosts(ctx context.Context, filter TeamFilter) ([]*DuplicateHostSet, error)
+
+	// MergeHosts consolidates the activities, script results, MDM state and
+	// software of the duplicate hosts under the target host and deletes the
+	// duplicate hosts.
+	MergeHosts(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// TenantStore contains methods for the tenants of a Fleet server shared by
+	// multiple customers.
+
+	// NewTenant creates a new tenant with a new encryption key.
+	NewTenant(ctx context.Context, tenant *Tenant) (*Tenant, error)
+	// Tenant returns the tenant with the given id.
+	Tenant(ctx context.Context, id uint) (*Tenant, error)
+	// ListTenants returns all the tenants, sorted by name.
+	ListTenants(ctx context.Context) ([]*Tenant, error)
+	// SaveTenant updates the name, description and config of the tenant.
+	SaveTenant(ctx context.Context, tenant *Tenant) (*Tenant, error)
+	// DeleteTenant deletes the tenant and its MDM assets. It fails with a
+	// foreign key error if the tenant still has teams or users.
+	DeleteTenant(ctx context.Context, id uint) error
+
+	// SetTeamsTenant moves the teams to the tenant, or out of any tenant if
+	// tenantID is nil.
+	SetTeamsTenant(ctx context.Context, tenantID *uint, teamIDs []uint) error
+	// SetUsersTenant moves the users to the tenant, or out of any tenant if
+	// tenantID is nil.
+	SetUsersTenant(ctx context.Context, tenantID *uint, userIDs []uint) error
+
+	// TenantAppConfig returns the app config with the tenant's config
+	// overrides applied.
+	TenantAppConfig(ctx context.Context, tenantID uint) (*AppConfig, error)
+
+	// SetTenantMDMAssets inserts or replaces the MDM assets of the tenant,
+	// encrypted with the tenant's encryption key.
+	SetTenantMDMAssets(ctx context.Context, tenantID uint, assets []TenantMDMAsset) error
+	// GetTenantMDMAssets returns the decrypted MDM assets of the tenant with the
+	// given names. Assets that are not set are not in the returned map.
+	GetTenantMDMAssets(ctx context.Context, tenantID uint, names []TenantMDMAssetName) (map[TenantMDMAssetName][]byte, error)
+	// RotateTenantEncryptionKey generates a new encryption key for the tenant
+	// and re-encrypts the tenant's MDM assets with it.
+	RotateTenantEncryptionKey(ctx context.Context, tenantID uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// DataExportStore contains methods for the CSV and XLSX exports generated in
+	// the background.
+
+	// NewDataExport creates a pending export.
+	NewDataExport(ctx context.Context, export *DataExport) (*DataExport, error)
+	// DataExport returns the export, without its content.
+	DataExport(ctx context.Context, id uint) (*DataExport, error)
+	// DataExportContent returns the content of the generated file of the
+	// export, nil if it is not completed.
+	DataExportContent(ctx context.Context, id uint) ([]byte, error)
+	// ListPendingDataExports returns the exports that were not generated yet,
+	// oldest first.
+	ListPendingDataExports(ctx context.Context) ([]*DataExport, error)
+	// CompleteDataExport stores the generated file of the export, or marks it
+	// as failed if exportErr is not empty.
+	CompleteDataExport(ctx context.Context, id uint, data []byte, exportErr string) error
+	// CleanupDataExports deletes the exports created before the given time and
+	// returns the number of deleted exports.
+	CleanupDataExports(ctx context.Context, createdBefore time.Time) (int64, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// AppConfigStore contains method for saving and retrieving application configuration
 
@@ -451,7 +811,7 @@ type Datastore interface {
 	ResultCountForQueryAndHost(ctx context.Context, queryID, hostID uint) (int, error)
 	OverwriteQueryResultRows(ctx context.Context, rows []*ScheduledQueryResultRow) error
 	// CleanupDiscardedQueryResults deletes all query results for queries with DiscardData enabled.
-	// Used in cleanups_then_aggregation cron to cleanup rows that were inserted immediately
+	// Used in database_maintenance cron to cleanup rows that were inserted immediately
 	// after DiscardData was set to true due to query caching.
 	CleanupDiscardedQueryResults(ctx context.Context) error
 
@@ -583,6 +943,27 @@ type Datastore interface {
 	ListHostPastActivities(ctx context.Context, hostID uint, opt ListOptions) ([]*Activity, *PaginationMetadata, error)
 	IsExecutionPendingForHost(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// AdminNotificationsStore
+
+	// NewAdminNotification records the admin notification. If a notification
+	// with the same key exists, it is updated with the new title, severity and
+	// details and its number of occurrences is incremented instead, without
+	// changing its read state.
+	NewAdminNotification(ctx context.Context, notification *AdminNotification) error
+	// ListAdminNotifications returns the admin notifications with their read
+	// state for the user, most recently raised first.
+	ListAdminNotifications(ctx context.Context, userID uint, opts AdminNotificationListOptions) ([]*AdminNotification, *PaginationMetadata, error)
+	// CountUnreadAdminNotifications returns the number of admin notifications
+	// the user did not read.
+	CountUnreadAdminNotifications(ctx context.Context, userID uint) (int, error)
+	// MarkAdminNotificationsRead marks the admin notifications as read for the
+	// user. If notificationIDs is empty, all the notifications are marked as
+	// read.
+	MarkAdminNotificationsRead(ctx context.Context, userID uint, notificationIDs []uint) error
+	// DeleteAdminNotification dismisses the admin notification for all users.
+	DeleteAdminNotification(ctx context.Context, id uint) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// StatisticsStore
 
@@ -592,6 +973,19 @@ type Datastore interface {
 	// statistics.
 	CleanupStatistics(ctx context.Context) error
 
+	// IncrementUsageAnalyticsAPIRequests adds the counts of API requests to the
+	// detailed usage analytics.
+	IncrementUsageAnalyticsAPIRequests(ctx context.Context, counts []UsageAnalyticsAPIRequestCount) error
+	// RecordUsageAnalyticsHostCount records the number of hosts currently
+	// enrolled as the host count of the day of date.
+	RecordUsageAnalyticsHostCount(ctx context.Context, date time.Time) error
+	// CleanupUsageAnalytics deletes the detailed usage analytics of the days
+	// before olderThan.
+	CleanupUsageAnalytics(ctx context.Context, olderThan time.Time) error
+	// GetUsageAnalyticsDashboard returns the detailed usage analytics since the
+	// day of since.
+	GetUsageAnalyticsDashboard(ctx context.Context, since time.Time, config config.FleetConfig) (*UsageAnalyticsDashboard, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// GlobalPoliciesStore
 
@@ -612,6 +1006,16 @@ type Datastore interface {
 	DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error)
 	CountPolicies(ctx context.Context, teamID *uint, matchQuery string) (int, error)
 	UpdateHostPolicyCounts(ctx context.Context) error
+	// RecordPolicyComplianceHistory records the current number of passing and
+	// failing hosts of the policies (as last computed by UpdateHostPolicyCounts)
+	// as the counts of the day of date.
+	RecordPolicyComplianceHistory(ctx context.Context, date time.Time) error
+	// CleanupPolicyComplianceHistory deletes the daily policy compliance counts
+	// of the days before olderThan.
+	CleanupPolicyComplianceHistory(ctx context.Context, olderThan time.Time) error
+	// GetPolicyComplianceTrends returns the daily policy compliance counts,
+	// summed over the policies matching the options.
+	GetPolicyComplianceTrends(ctx context.Context, opts PolicyComplianceTrendsOptions) ([]PolicyComplianceDay, error)
 
 	PolicyQueriesForHost(ctx context.Context, host *Host) (map[string]string, error)
 
@@ -636,6 +1040,11 @@ type Datastore interface {
 
 	ListSoftware(ctx context.Context, opt SoftwareListOptions) ([]Software, *PaginationMetadata, error)
 	CountSoftware(ctx context.Context, opt SoftwareListOptions) (int, error)
+	// StreamSoftware calls fn with successive batches of at most batchSize
+	// software matching the options, sorted by ID, using keyset pagination on
+	// the software ID. The pagination and ordering options are ignored.
+	// Streaming stops and the error is returned if fn returns an error.
+	StreamSoftware(ctx context.Context, opt SoftwareListOptions, batchSize int, fn func([]Software) error) error
 	// DeleteVulnerabilities deletes the given list of vulnerabilities identified by CPE+CVE.
 	DeleteSoftwareVulnerabilities(ctx context.Context, vulnerabilities []SoftwareVulnerability) error
 	// DeleteOutOfDateVulnerabilities deletes 'software_cve' entries from the provided source where
@@ -699,13 +1108,24 @@ type Datastore interface {
 	GetLatestCronStats(ctx context.Context, name string) ([]CronStats, error)
 	// InsertCronStats inserts cron stats for the named cron schedule.
 	InsertCronStats(ctx context.Context, statsType CronStatsType, name string, instance string, status CronStatsStatus) (int, error)
-	// UpdateCronStats updates the status of the identified cron stats record.
-	UpdateCronStats(ctx context.Context, id int, status CronStatsStatus) error
+	// UpdateCronStats updates the status and the job errors of the identified cron stats record.
+	UpdateCronStats(ctx context.Context, id int, status CronStatsStatus, cronErrors CronScheduleErrors) error
 	// UpdateAllCronStatsForInstance updates all records for the identified instance with the
 	// specified statuses
 	UpdateAllCronStatsForInstance(ctx context.Context, instance string, fromStatus CronStatsStatus, toStatus CronStatsStatus) error
 	// CleanupCronStats cleans up expired cron stats.
 	CleanupCronStats(ctx context.Context) error
+	// ListLatestCronStats returns, for each cron schedule and stats type, the
+	// most recent pending run and the most recent finished run, if any.
+	ListLatestCronStats(ctx context.Context) ([]CronStats, error)
+	// IsCronSchedulePaused returns true if the scheduled runs of the named
+	// cron schedule are paused.
+	IsCronSchedulePaused(ctx context.Context, name string) (bool, error)
+	// SetCronSchedulePaused pauses or resumes the scheduled runs of the named
+	// cron schedule.
+	SetCronSchedulePaused(ctx context.Context, name string, paused bool) error
+	// ListPausedCronSchedules returns the names of the paused cron schedules.
+	ListPausedCronSchedules(ctx context.Context) ([]string, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Aggregated Stats
@@ -762,6 +1182,24 @@ type Datastore interface {
 	// mutations performed: what was inserted and what was removed.
 	UpdateHostSoftware(ctx context.Context, hostID uint, software []Software) (*UpdateHostSoftwareDBResult, error)
 
+	// UpdateHostSoftwareFromSource updates the software list of a host with the
+	// software reported by the given inventory source. The software installed on
+	// the host is the union of the software reported by all sources, and when
+	// sources report different versions of the same software, the version of
+	// the source with the highest precedence wins. UpdateHostSoftware is
+	// equivalent to calling it with the osquery source.
+	UpdateHostSoftwareFromSource(ctx context.Context, hostID uint, source SoftwareInventorySource, software []Software) (*UpdateHostSoftwareDBResult, error)
+
+	// UpdateHostSoftwareUpdatedAt records that the software of the host was
+	// refreshed now, even if it did not change.
+	UpdateHostSoftwareUpdatedAt(ctx context.Context, hostID uint) error
+
+	// DeleteHostSoftwareByBundleIdentifier removes the software with the
+	// given bundle identifier from the software inventory of the host,
+	// whatever the source that reported it, e.g. after the app was removed by
+	// MDM.
+	DeleteHostSoftwareByBundleIdentifier(ctx context.Context, hostID uint, bundleIdentifier string) error
+
 	// UpdateHostSoftwareInstalledPaths looks at all software for 'hostID' and based on the contents of
 	// 'reported', either inserts or deletes the corresponding entries in the
 	// 'host_software_installed_paths' table. 'reported' is a set of
@@ -827,6 +1265,28 @@ type Datastore interface {
 	SetHostsDiskEncryptionKeyStatus(ctx context.Context, hostIDs []uint, encryptable bool, threshold time.Time) error
 	// GetHostDiskEncryptionKey returns the encryption key information for a given host
 	GetHostDiskEncryptionKey(ctx context.Context, hostID uint) (*HostDiskEncryptionKey, error)
+	// SetHostDiskEncryptionKeyRotationPending records the UUID of the
+	// RotateFileVaultKey command sent to rotate the escrowed key of the host.
+	// It returns false if a rotation is already pending for the host.
+	SetHostDiskEncryptionKeyRotationPending(ctx context.Context, hostID uint, cmdUUID string) (bool, error)
+	// UpdateHostDiskEncryptionKeyFromRotateResult stores the new, encrypted key
+	// reported by the host in response to the pending RotateFileVaultKey
+	// command identified by cmdUUID, its decryptable status is verified again.
+	// If encryptedBase64Key is empty (the command failed), only the pending
+	// rotation is cleared and the current key is kept.
+	UpdateHostDiskEncryptionKeyFromRotateResult(ctx context.Context, hostUUID, cmdUUID, encryptedBase64Key string) error
+	// SaveLUKSDiskEncryptionKey stores the LUKS passphrase escrowed by a Linux
+	// host, encrypted with the server private key, along with the key slot it
+	// was added to. If clientError is not empty, only the error is recorded and
+	// the previously escrowed passphrase, if any, is kept.
+	SaveLUKSDiskEncryptionKey(ctx context.Context, hostID uint, passphrase string, keySlot *uint, clientError string) error
+	// GetHostLUKSDiskEncryptionKey returns the decrypted LUKS passphrase
+	// escrowed by a Linux host.
+	GetHostLUKSDiskEncryptionKey(ctx context.Context, hostID uint) (*HostDiskEncryptionKey, error)
+	// SetHostLinuxDiskEncryptionEnforcement stores the state of the
+	// enforcement of disk encryption reported by a Linux host whose root
+	// volume is not encrypted.
+	SetHostLinuxDiskEncryptionEnforcement(ctx context.Context, hostID uint, status LinuxDiskEncryptionEnforcementStatus, detail string) error
 
 	SetDiskEncryptionResetStatus(ctx context.Context, hostID uint, status bool) error
 
@@ -835,12 +1295,34 @@ type Datastore interface {
 	// progress based on the provided arguments.
 	GetHostCertAssociationsToExpire(ctx context.Context, expiryDays, limit int) ([]SCEPIdentityAssociation, error)
 
+	// GetHostSCEPIdentityAssociation returns the association of the host with
+	// its most recent SCEP identity certificate.
+	GetHostSCEPIdentityAssociation(ctx context.Context, hostUUID string) (*SCEPIdentityAssociation, error)
+
 	// SetCommandForPendingSCEPRenewal tracks the command used to renew a scep certificate
 	SetCommandForPendingSCEPRenewal(ctx context.Context, assocs []SCEPIdentityAssociation, cmdUUID string) error
 
 	// CleanSCEPRenewRefs cleans all references after a successful SCEP renewal.
 	CleanSCEPRenewRefs(ctx context.Context, hostUUID string) error
 
+	// GetMDMAppleConfigProfileByHostCommandUUID returns the configuration
+	// profile installed on the host by the provided InstallProfile command.
+	GetMDMAppleConfigProfileByHostCommandUUID(ctx context.Context, hostUUID, cmdUUID string) (*MDMAppleConfigProfile, error)
+
+	// RecordHostMDMAppleProfileCertificate records that a profile with a
+	// SCEP-backed Wi-Fi or VPN payload was just installed on the host, which
+	// also completes any renewal in progress for that profile.
+	RecordHostMDMAppleProfileCertificate(ctx context.Context, hostUUID, profileUUID string) error
+
+	// GetHostMDMAppleProfileCertificatesToRenew returns the SCEP-backed
+	// profiles installed on hosts before installedBefore that are still
+	// installed and don't have a renewal in progress.
+	GetHostMDMAppleProfileCertificatesToRenew(ctx context.Context, installedBefore time.Time, limit int) ([]HostMDMAppleProfileCertificate, error)
+
+	// SetCommandForPendingProfileCertificateRenewal tracks the command used to
+	// re-deliver SCEP-backed profiles to renew their certificates.
+	SetCommandForPendingProfileCertificateRenewal(ctx context.Context, certs []HostMDMAppleProfileCertificate, cmdUUID string) error
+
 	// UpdateVerificationHostMacOSProfiles updates status of macOS profiles installed on a given
 	// host. The toVerify, toFail, and toRetry slices contain the identifiers of the profiles that
 	// should be verified, failed, and retried, respectively. For each profile in the toRetry slice,
@@ -858,7 +1340,7 @@ type Datastore interface {
 
 	// SetOrUpdateHostOrbitInfo inserts of updates the orbit info for a host
 	SetOrUpdateHostOrbitInfo(
-		ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool,
+		ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool, timezone sql.NullString,
 	) error
 
 	GetHostOrbitInfo(ctx context.Context, hostID uint) (*HostOrbitInfo, error)
@@ -868,6 +1350,29 @@ type Datastore interface {
 	// ReplaceHostBatteries creates or updates the battery mappings of a host.
 	ReplaceHostBatteries(ctx context.Context, id uint, mappings []*HostBattery) error
 
+	// ReplaceHostCertificates replaces the certificates of a host reported by
+	// the provided origin with the provided ones (as identified by their SHA-1
+	// fingerprint).
+	ReplaceHostCertificates(ctx context.Context, hostID uint, origin HostCertificateOrigin, certs []*HostCertificate) error
+
+	// ListHostCertificates returns the certificates of a host, from all
+	// origins, ordered by expiry.
+	ListHostCertificates(ctx context.Context, hostID uint) ([]*HostCertificate, error)
+
+	// SetOrUpdateHostCloudInstance creates or updates the cloud instance that
+	// the host runs on. If instance is nil, the host is not a cloud instance
+	// (anymore) and its cloud instance is deleted.
+	SetOrUpdateHostCloudInstance(ctx context.Context, hostID uint, instance *HostCloudInstance) error
+
+	// ListCertificateInventory returns the certificates found on the hosts
+	// visible to the team filter, aggregated across hosts.
+	ListCertificateInventory(ctx context.Context, filter TeamFilter, opts CertificateInventoryListOptions) ([]*CertificateInventoryItem, int, *PaginationMetadata, error)
+
+	// ListExpiringCertificates returns the certificates (across all hosts)
+	// that are still valid at from but expire before to, and that are found on
+	// more than hostsThreshold hosts, ordered by expiry.
+	ListExpiringCertificates(ctx context.Context, from, to time.Time, hostsThreshold uint) ([]*CertificateInventoryItem, error)
+
 	// VerifyEnrollSecret checks that the provided secret matches an active enroll secret. If it is successfully
 	// matched, that secret is returned. Otherwise, an error is returned.
 	VerifyEnrollSecret(ctx context.Context, secret string) (*EnrollSecret, error)
@@ -882,6 +1387,25 @@ type Datastore interface {
 	//	- If an entry for the host doesn't exist (osquery enrolls later) then it will create a new entry in the hosts table.
 	EnrollOrbit(ctx context.Context, isMDMEnabled bool, hostInfo OrbitHostInfo, orbitNodeKey string, teamID *uint) (*Host, error)
 
+	// GetHostEnrollmentCertificateBySHA256 returns the enrollment certificate
+	// with the provided fingerprint and the host it is bound to, a not found
+	// error if no host enrolled with it.
+	GetHostEnrollmentCertificateBySHA256(ctx context.Context, sha256 string) (*HostEnrollmentCertificate, error)
+
+	// RecordHostEnrollmentCertificate binds the enrollment certificate to its
+	// host. It is a no-op if the certificate is already bound.
+	RecordHostEnrollmentCertificate(ctx context.Context, cert *HostEnrollmentCertificate) error
+
+	// ListHostEnrollmentCertificates returns the enrollment certificates bound
+	// to the host, most recent first.
+	ListHostEnrollmentCertificates(ctx context.Context, hostID uint) ([]*HostEnrollmentCertificate, error)
+
+	// RevokeHostEnrollmentCertificates revokes the enrollment certificates
+	// bound to the host and, if any was revoked, clears its osquery and orbit
+	// node keys so that it must enroll again. It returns the number of
+	// certificates revoked.
+	RevokeHostEnrollmentCertificates(ctx context.Context, hostID uint) (int, error)
+
 	SerialUpdateHost(ctx context.Context, host *Host) error
 
 	///////////////////////////////////////////////////////////////////////////////
@@ -903,6 +1427,39 @@ type Datastore interface {
 	InnoDBStatus(ctx context.Context) (string, error)
 	ProcessList(ctx context.Context) ([]MySQLProcess, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// Database maintenance
+
+	// ListDatabaseMaintenanceRuns returns the last run of each database
+	// maintenance task.
+	ListDatabaseMaintenanceRuns(ctx context.Context) ([]*DatabaseMaintenanceRun, error)
+	// RecordDatabaseMaintenanceRun records the last run of a database
+	// maintenance task, replacing its previous run.
+	RecordDatabaseMaintenanceRun(ctx context.Context, run *DatabaseMaintenanceRun) error
+	// SnapshotTableSizes records the current size of the tables of the
+	// database, as estimated by MySQL, and deletes the snapshots older than the
+	// retention period of the table growth trends.
+	SnapshotTableSizes(ctx context.Context) error
+	// ListTableGrowth returns the size of each table of the latest snapshot and
+	// its growth over the last day and week, the largest tables first.
+	ListTableGrowth(ctx context.Context) ([]*TableGrowth, error)
+	// AnalyzeTables updates the index statistics of the tables.
+	AnalyzeTables(ctx context.Context, tables []string) error
+	// OptimizeTables rebuilds the tables to reclaim their unused space.
+	OptimizeTables(ctx context.Context, tables []string) error
+	// CleanupExpiredSessions deletes the sessions that have been idle for
+	// longer than idleTimeout or that were created more than maxLifetime ago,
+	// a duration of 0 being unlimited. The sessions of API-only users never
+	// expire. It returns the number of deleted sessions.
+	CleanupExpiredSessions(ctx context.Context, idleTimeout, maxLifetime time.Duration) (int64, error)
+	// CleanupExpiredInvites deletes the invites that were created before
+	// createdBefore and returns the number of deleted invites.
+	CleanupExpiredInvites(ctx context.Context, createdBefore time.Time) (int64, error)
+	// CleanupOrphanedHostRows deletes the rows of the tables referencing hosts
+	// that reference hosts that no longer exist, e.g. rows ingested for a host
+	// while it was being deleted. It returns the number of deleted rows.
+	CleanupOrphanedHostRows(ctx context.Context) (int64, error)
+
 	// WindowsUpdates Store
 	ListWindowsUpdatesByHostID(ctx context.Context, hostID uint) ([]WindowsUpdate, error)
 	InsertWindowsUpdates(ctx context.Context, hostID uint, updates []WindowsUpdate) error
@@ -934,6 +1491,17 @@ type Datastore interface {
 	// UpdateVulnerabilityHostCounts updates hosts counts for all vulnerabilities.
 	UpdateVulnerabilityHostCounts(ctx context.Context) error
 
+	// UpdateHostCVEDetections records the vulnerabilities newly detected on
+	// the hosts as first detected at now, and removes the vulnerabilities that
+	// are not detected anymore.
+	UpdateHostCVEDetections(ctx context.Context, now time.Time) error
+	// ListVulnerabilitySLABreaches returns the vulnerabilities that are overdue
+	// on the hosts of the team, most severe first.
+	ListVulnerabilitySLABreaches(ctx context.Context, opts VulnerabilitySLABreachOptions) ([]*VulnerabilitySLABreach, error)
+	// MarkVulnerabilitySLABreachesNotified records that the breaches listed
+	// with the same options were escalated.
+	MarkVulnerabilitySLABreachesNotified(ctx context.Context, opts VulnerabilitySLABreachOptions) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// Apple MDM
 
@@ -1070,12 +1638,27 @@ type Datastore interface {
 	// GetNanoMDMEnrollment returns the nano enrollment information for the device id.
 	GetNanoMDMEnrollment(ctx context.Context, id string) (*NanoEnrollment, error)
 
+	// ListNanoMDMUserEnrollmentDeviceIDs returns the subset of the provided
+	// device ids that are enrolled via Apple User Enrollment (BYOD).
+	ListNanoMDMUserEnrollmentDeviceIDs(ctx context.Context, deviceIDs []string) ([]string, error)
+
 	// IncreasePolicyAutomationIteration marks the policy to fire automation again.
 	IncreasePolicyAutomationIteration(ctx context.Context, policyID uint) error
 
 	// OutdatedAutomationBatch returns a batch of hosts that had a failing policy.
 	OutdatedAutomationBatch(ctx context.Context) ([]PolicyFailure, error)
 
+	// ListPolicyFailingHosts returns the hosts currently failing the policy,
+	// sorted by ID, i.e. the hosts that trigger the policy's automation when it
+	// is enabled.
+	ListPolicyFailingHosts(ctx context.Context, policyID uint) ([]PolicySetHost, error)
+
+	// StreamPolicyMembership calls fn with successive batches of at most
+	// batchSize hosts that pass (or fail, if passes is false) the policy,
+	// sorted by ID, using keyset pagination on the host ID. Streaming stops and
+	// the error is returned if fn returns an error.
+	StreamPolicyMembership(ctx context.Context, policyID uint, passes bool, batchSize int, fn func([]PolicySetHost) error) error
+
 	// ListMDMAppleProfilesToInstall returns all the profiles that should
 	// be installed based on diffing the ideal state vs the state we have
 	// registered in `host_mdm_apple_profiles`
@@ -1108,6 +1691,25 @@ type Datastore interface {
 	// GetMDMAppleCommandRequest type returns the request type for the given command
 	GetMDMAppleCommandRequestType(ctx context.Context, commandUUID string) (string, error)
 
+	// ListIOSAndIPadOSToRefetch returns the UUIDs of the MDM-enrolled iPhones
+	// and iPads whose details were last updated more than interval ago or
+	// that have a refetch requested, and that don't already have a pending
+	// refetch command.
+	ListIOSAndIPadOSToRefetch(ctx context.Context, interval time.Duration) ([]string, error)
+
+	// ListAppleHostsForMDMSoftwareRefetch returns the UUIDs of the
+	// MDM-enrolled Apple hosts that are not enrolled in osquery and whose
+	// software was last updated more than interval ago (or that have a
+	// refetch requested), and that don't already have a pending software
+	// refetch command.
+	ListAppleHostsForMDMSoftwareRefetch(ctx context.Context, interval time.Duration) ([]string, error)
+
+	// ListHostsWithNotNowMDMAppleCommands returns the UUIDs of the enabled
+	// Apple MDM enrollments that last responded NotNow to a pending command of
+	// that request type more than retryInterval ago, for commands enqueued less
+	// than maxAge ago.
+	ListHostsWithNotNowMDMAppleCommands(ctx context.Context, requestType string, retryInterval, maxAge time.Duration) ([]string, error)
+
 	// GetMDMAppleProfilesSummary summarizes the current state of MDM configuration profiles on
 	// each host in the specified team (or, if no team is specified, each host that is not assigned
 	// to any team).
@@ -1149,6 +1751,120 @@ type Datastore interface {
 	// GetHostMDMMacOSSetup returns the MDM macOS setup information for the specified host id.
 	GetHostMDMMacOSSetup(ctx context.Context, hostID uint) (*HostMDMMacOSSetup, error)
 
+	// RecordHostFleetdInstall records the latest command used to install fleetd
+	// in a host and the number of times it was retried.
+	RecordHostFleetdInstall(ctx context.Context, commandUUID string, hostUUID string, retries uint) error
+
+	// GetHostMDMFleetdInstall returns the status of the latest command used to
+	// install fleetd in the specified host uuid.
+	GetHostMDMFleetdInstall(ctx context.Context, hostUUID string) (*HostMDMFleetdInstall, error)
+
+	// SetOrUpdateHostMDMAppleSecurityInfo creates or replaces the security
+	// information reported by the macOS host via the SecurityInfo MDM command.
+	SetOrUpdateHostMDMAppleSecurityInfo(ctx context.Context, hostID uint, info *HostMDMAppleSecurityInfo) error
+
+	// GetHostMDMAppleSecurityInfo returns the security information reported by
+	// the macOS host via the SecurityInfo MDM command, a not found error if it
+	// never reported it.
+	GetHostMDMAppleSecurityInfo(ctx context.Context, hostID uint) (*HostMDMAppleSecurityInfo, error)
+
+	// NewHostLostMode records that the command to enable Lost Mode with the
+	// provided settings was sent to the host, replacing any previous Lost Mode
+	// of the host.
+	NewHostLostMode(ctx context.Context, hostID uint, settings HostLostModeSettings, enableCommandUUID string) error
+
+	// GetHostLostMode returns the Lost Mode of the host, a not found error if
+	// the host is not in Lost Mode and no command to enable it is pending.
+	GetHostLostMode(ctx context.Context, hostID uint) (*HostLostMode, error)
+
+	// SetHostLostModePendingDisable records that the command to disable Lost
+	// Mode was sent to the host.
+	SetHostLostModePendingDisable(ctx context.Context, hostID uint, disableCommandUUID string) error
+
+	// UpdateHostLostModeFromAppleMDMResult updates the Lost Mode of the host
+	// from the result of the EnableLostMode or DisableLostMode command.
+	UpdateHostLostModeFromAppleMDMResult(ctx context.Context, hostUUID, cmdUUID, requestType string, succeeded bool) error
+
+	// NewHostLocation records the location reported by the host.
+	NewHostLocation(ctx context.Context, loc *HostMDMAppleLocation) error
+
+	// GetHostLastLocation returns the last location reported by the host, a
+	// not found error if it never reported one.
+	GetHostLastLocation(ctx context.Context, hostID uint) (*HostMDMAppleLocation, error)
+
+	// SetHostMDMAppleActivationLockBypassCode stores the Activation Lock
+	// bypass code escrowed by the host, encrypted with the server private key.
+	SetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostUUID, bypassCode string) error
+
+	// GetHostMDMAppleActivationLockBypassCode returns the decrypted Activation
+	// Lock bypass code of the host, a not found error if the host did not
+	// escrow one or it was cleared.
+	GetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostID uint) (*HostMDMAppleActivationLockBypassCode, error)
+
+	// SetHostMDMAppleActivationLockBypassCodePendingClear records that the
+	// ClearActivationLockBypassCode command was sent to the host.
+	SetHostMDMAppleActivationLockBypassCodePendingClear(ctx context.Context, hostID uint, clearCommandUUID string) error
+
+	// UpdateHostMDMAppleActivationLockBypassCodeFromClearResult deletes the
+	// bypass code of the host if it acknowledged the
+	// ClearActivationLockBypassCode command, or clears the pending command if
+	// it failed.
+	UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx context.Context, hostUUID, cmdUUID string, succeeded bool) error
+
+	// ListHostsForAppleRecoveryLockRotation returns the Apple silicon Macs
+	// enrolled in Fleet MDM that need a new Recovery Lock password, with their
+	// current (decrypted) password if they have one. Those are the hosts
+	// without a password, the hosts whose password was set before
+	// rotateBefore, and the hosts whose last SetRecoveryLock command failed
+	// before retryBefore. The hosts whose escrowed password failed the
+	// verification are excluded, as their current password is unknown.
+	ListHostsForAppleRecoveryLockRotation(ctx context.Context, rotateBefore, retryBefore time.Time, limit int) ([]*HostMDMAppleRecoveryLockPassword, error)
+
+	// SetHostMDMAppleRecoveryLockPending records the new Recovery Lock
+	// password sent to the host with the SetRecoveryLock command, encrypted
+	// with the server private key.
+	SetHostMDMAppleRecoveryLockPending(ctx context.Context, hostID uint, newPassword, setCmdUUID string) error
+
+	// UpdateHostMDMAppleRecoveryLockFromSetResult stores the pending Recovery
+	// Lock password as the password of the host if it acknowledged the
+	// SetRecoveryLock command (errorMessage is empty), or records the error.
+	// It returns the updated password if the host acknowledged the command,
+	// nil otherwise.
+	UpdateHostMDMAppleRecoveryLockFromSetResult(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*HostMDMAppleRecoveryLockPassword, error)
+
+	// SetHostMDMAppleRecoveryLockPendingVerify records that the
+	// VerifyRecoveryLock command was sent to the host.
+	SetHostMDMAppleRecoveryLockPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error
+
+	// UpdateHostMDMAppleRecoveryLockFromVerifyResult records whether the host
+	// verified the escrowed Recovery Lock password.
+	UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx context.Context, hostUUID, cmdUUID string, verified bool) error
+
+	// GetHostMDMAppleRecoveryLockPassword returns the decrypted Recovery Lock
+	// password of the host, a not found error if the host has none.
+	GetHostMDMAppleRecoveryLockPassword(ctx context.Context, hostID uint) (*HostMDMAppleRecoveryLockPassword, error)
+
+	// UpdateHostMDMAppleFirmwarePasswordFromSetResult stores the pending
+	// firmware password as the password of the host when it acknowledges the
+	// SetFirmwarePassword command (errorMessage is empty), or records the
+	// error. It returns the updated password on success, nil if the command
+	// is not the pending SetFirmwarePassword command of the host.
+	UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*HostMDMAppleFirmwarePassword, error)
+
+	// SetHostMDMAppleFirmwarePasswordPendingVerify records that the
+	// VerifyFirmwarePassword command was sent to the host.
+	SetHostMDMAppleFirmwarePasswordPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error
+
+	// UpdateHostMDMAppleFirmwarePasswordFromVerifyResult records whether the
+	// host verified the escrowed firmware password.
+	UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx context.Context, hostUUID, cmdUUID string, verified bool) error
+
+	// GetHostMDMAppleFirmwarePassword returns the decrypted firmware password
+	// of the host, with an empty password if the first SetFirmwarePassword
+	// command is pending. It returns a not found error if no password was
+	// ever set on the host.
+	GetHostMDMAppleFirmwarePassword(ctx context.Context, hostID uint) (*HostMDMAppleFirmwarePassword, error)
+
 	// MDMGetEULAMetadata returns metadata information about the EULA
 	// filed stored in the database.
 	MDMGetEULAMetadata(ctx context.Context) (*MDMEULA, error)
@@ -1288,10 +2004,63 @@ type Datastore interface {
 	// corresponding to the criteria.
 	ListMDMConfigProfiles(ctx context.Context, teamID *uint, opt ListOptions) ([]*MDMConfigProfilePayload, *PaginationMetadata, error)
 
+	// PreviewMDMProfileTargets returns the MDM-enrolled hosts of the team (or
+	// no team if teamID is nil) that a configuration profile for the platform
+	// ("darwin" or "windows") with those labels would be delivered to.
+	PreviewMDMProfileTargets(ctx context.Context, teamID *uint, platform string, labels []ConfigurationProfileLabel) (*MDMProfileTargetsPreview, error)
+
 	// ResendHostMDMProfile updates the host's profile status to NULL thereby triggering the profile
 	// to be resent upon the next cron run.
 	ResendHostMDMProfile(ctx context.Context, hostUUID string, profileUUID string) error
 
+	// NewMDMProfileRedeliveryCampaign creates a campaign that redelivers the
+	// profile to the hosts that have it installed. It returns an
+	// AlreadyExists error if a campaign is already in progress for the
+	// profile.
+	NewMDMProfileRedeliveryCampaign(ctx context.Context, campaign *MDMProfileRedeliveryCampaign) (*MDMProfileRedeliveryCampaign, error)
+
+	// MDMProfileRedeliveryCampaign returns the profile redelivery campaign.
+	MDMProfileRedeliveryCampaign(ctx context.Context, id uint) (*MDMProfileRedeliveryCampaign, error)
+
+	// ListMDMProfileRedeliveryCampaigns returns the profile redelivery
+	// campaigns matching the options, most recent first.
+	ListMDMProfileRedeliveryCampaigns(ctx context.Context, opts MDMProfileRedeliveryCampaignListOptions) ([]*MDMProfileRedeliveryCampaign, error)
+
+	// ListRunningMDMProfileRedeliveryCampaigns returns the running profile
+	// redelivery campaigns of all teams.
+	ListRunningMDMProfileRedeliveryCampaigns(ctx context.Context) ([]*MDMProfileRedeliveryCampaign, error)
+
+	// UpdateMDMProfileRedeliveryCampaign saves the status, rate and last
+	// batch time of the campaign.
+	UpdateMDMProfileRedeliveryCampaign(ctx context.Context, campaign *MDMProfileRedeliveryCampaign) error
+
+	// RedeliverMDMProfileCampaignBatch triggers the redelivery of the profile
+	// to the next batchSize hosts of the running campaign, and marks the
+	// campaign as completed once all its hosts are processed. It returns the
+	// number of hosts of the batch.
+	RedeliverMDMProfileCampaignBatch(ctx context.Context, campaignID uint, batchSize uint, now time.Time) (uint, error)
+
+	// GetMDMProfileRollout returns the staged rollout of the profile, along
+	// with the verification results of its canary hosts.
+	GetMDMProfileRollout(ctx context.Context, profileUUID string) (*MDMProfileRollout, error)
+
+	// ListActiveMDMProfileRollouts returns the staged rollouts in the canary
+	// status, without their results.
+	ListActiveMDMProfileRollouts(ctx context.Context) ([]*MDMProfileRollout, error)
+
+	// GetMDMProfileRolloutResults returns the verification results of the
+	// canary hosts of the profile's staged rollout.
+	GetMDMProfileRolloutResults(ctx context.Context, profileUUID string) (*MDMProfileRolloutResults, error)
+
+	// UpdateMDMProfileRolloutStatus sets the status of the profile's staged
+	// rollout. The canary status restarts the rollout, the other statuses
+	// finish it.
+	UpdateMDMProfileRolloutStatus(ctx context.Context, profileUUID string, status MDMProfileRolloutStatus, reason string) error
+
+	// CleanupMDMProfileRollouts deletes the staged rollouts of the profiles
+	// that no longer exist.
+	CleanupMDMProfileRollouts(ctx context.Context) error
+
 	// GetHostMDMProfileInstallStatus returns the status of the profile for the host.
 	GetHostMDMProfileInstallStatus(ctx context.Context, hostUUID string, profileUUID string) (MDMDeliveryStatus, error)
 
@@ -1309,6 +2078,10 @@ type Datastore interface {
 	// each Windows host in the specified team (or, if no team is specified, each host that is not assigned
 	// to any team).
 	GetMDMWindowsBitLockerSummary(ctx context.Context, teamID *uint) (*MDMWindowsBitLockerSummary, error)
+	// GetMDMLinuxDiskEncryptionSummary summarizes the current state of disk
+	// encryption on each Linux host with fleetd in the specified team (or, if
+	// no team is specified, each host that is not assigned to any team).
+	GetMDMLinuxDiskEncryptionSummary(ctx context.Context, teamID *uint) (*MDMLinuxDiskEncryptionSummary, error)
 	// GetMDMWindowsBitLockerStatus returns the disk encryption status for a given host
 	//
 	// Note that the returned status will be nil if the host is reported to be a Windows
@@ -1363,6 +2136,102 @@ type Datastore interface {
 	// SetOrUpdateMDMAppleDeclaration upserts the MDM Apple declaration.
 	SetOrUpdateMDMAppleDeclaration(ctx context.Context, declaration *MDMAppleDeclaration) (*MDMAppleDeclaration, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// Host Log Collections
+
+	// NewHostLogCollection creates a new request to collect logs from a host
+	// and returns it with its generated execution id.
+	NewHostLogCollection(ctx context.Context, payload *HostLogCollectionPayload) (*HostLogCollection, error)
+	// GetHostLogCollection returns the log collection request identified by
+	// the execution id, without the collected logs.
+	GetHostLogCollection(ctx context.Context, execID string) (*HostLogCollection, error)
+	// GetHostLogCollectionData returns the compressed logs uploaded for the log
+	// collection request identified by the execution id.
+	GetHostLogCollectionData(ctx context.Context, execID string) ([]byte, error)
+	// ListHostLogCollections returns the log collection requests of the host,
+	// most recent first.
+	ListHostLogCollections(ctx context.Context, hostID uint) ([]*HostLogCollection, error)
+	// ListPendingHostLogCollections returns the log collection requests of
+	// the host that did not receive a result yet and are not older than
+	// MaxHostLogCollectionPendingAge.
+	ListPendingHostLogCollections(ctx context.Context, hostID uint) ([]*HostLogCollection, error)
+	// SetHostLogCollectionResult stores the result (the collected logs or an
+	// error) of a log collection request. It returns the updated log
+	// collection if the result was stored, or nil if the request already had
+	// a result.
+	SetHostLogCollectionResult(ctx context.Context, result *HostLogCollectionResultPayload) (*HostLogCollection, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Host File Retrievals
+
+	// NewHostFileRetrieval creates a new request to retrieve a file from a host
+	// and returns it with its generated execution id.
+	NewHostFileRetrieval(ctx context.Context, payload *HostFileRetrievalPayload) (*HostFileRetrieval, error)
+	// GetHostFileRetrieval returns the file retrieval request identified by
+	// the execution id, without the file contents.
+	GetHostFileRetrieval(ctx context.Context, execID string) (*HostFileRetrieval, error)
+	// GetHostFileRetrievalData returns the contents of the file uploaded for
+	// the file retrieval request identified by the execution id.
+	GetHostFileRetrievalData(ctx context.Context, execID string) ([]byte, error)
+	// ListHostFileRetrievals returns the file retrieval requests of the host,
+	// most recent first.
+	ListHostFileRetrievals(ctx context.Context, hostID uint) ([]*HostFileRetrieval, error)
+	// ListPendingHostFileRetrievals returns the file retrieval requests of the
+	// host that did not receive a result yet and are not older than
+	// MaxHostFileRetrievalPendingAge.
+	ListPendingHostFileRetrievals(ctx context.Context, hostID uint) ([]*HostFileRetrieval, error)
+	// SetHostFileRetrievalResult stores the result (the file contents or an
+	// error) of a file retrieval request. It returns the updated file
+	// retrieval if the result was stored, or nil if the request already had a
+	// result.
+	SetHostFileRetrievalResult(ctx context.Context, result *HostFileRetrievalResultPayload) (*HostFileRetrieval, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Host Pprof Captures
+
+	// NewHostPprofCapture creates a new request to capture a pprof profile of
+	// the fleetd agent of a host and returns it with its generated execution
+	// id.
+	NewHostPprofCapture(ctx context.Context, payload *HostPprofCapturePayload) (*HostPprofCapture, error)
+	// GetHostPprofCapture returns the pprof capture request identified by the
+	// execution id, without the profile data.
+	GetHostPprofCapture(ctx context.Context, execID string) (*HostPprofCapture, error)
+	// GetHostPprofCaptureData returns the profile uploaded for the pprof
+	// capture request identified by the execution id.
+	GetHostPprofCaptureData(ctx context.Context, execID string) ([]byte, error)
+	// ListHostPprofCaptures returns the pprof capture requests of the host,
+	// most recent first.
+	ListHostPprofCaptures(ctx context.Context, hostID uint) ([]*HostPprofCapture, error)
+	// ListPendingHostPprofCaptures returns the pprof capture requests of the
+	// host that did not receive a result yet and are not older than
+	// MaxHostPprofCapturePendingAge.
+	ListPendingHostPprofCaptures(ctx context.Context, hostID uint) ([]*HostPprofCapture, error)
+	// SetHostPprofCaptureResult stores the result (the profile or an error) of
+	// a pprof capture request. It returns the updated pprof capture if the
+	// result was stored, or nil if the request already had a result.
+	SetHostPprofCaptureResult(ctx context.Context, result *HostPprofCaptureResultPayload) (*HostPprofCapture, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Host Remote Queries
+
+	// NewHostRemoteQuery creates a new request to run a one-off osquery query
+	// on a host and returns it with its generated execution id.
+	NewHostRemoteQuery(ctx context.Context, payload *HostRemoteQueryPayload) (*HostRemoteQuery, error)
+	// GetHostRemoteQuery returns the remote query request identified by the
+	// execution id, with the rows returned by the host.
+	GetHostRemoteQuery(ctx context.Context, execID string) (*HostRemoteQuery, error)
+	// ListHostRemoteQueries returns the remote query requests of the host,
+	// most recent first, without their rows.
+	ListHostRemoteQueries(ctx context.Context, hostID uint) ([]*HostRemoteQuery, error)
+	// ListPendingHostRemoteQueries returns the remote query requests of the
+	// host that did not receive a result yet and are not older than
+	// MaxHostRemoteQueryPendingAge.
+	ListPendingHostRemoteQueries(ctx context.Context, hostID uint) ([]*HostRemoteQuery, error)
+	// SetHostRemoteQueryResult stores the result (the rows or an error) of a
+	// remote query request. It returns the updated remote query if the result
+	// was stored, or nil if the request already had a result.
+	SetHostRemoteQueryResult(ctx context.Context, result *HostRemoteQueryResultPayload) (*HostRemoteQuery, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// Host Script Results
 
@@ -1410,6 +2279,49 @@ type Datastore interface {
 	// BatchSetScripts sets the scripts for the given team or no team.
 	BatchSetScripts(ctx context.Context, tmID *uint, scripts []*Script) error
 
+	// SetScriptSharedWithAllTeams shares the "no team" script with all teams,
+	// or stops sharing it (in which case the opt-outs of the teams are
+	// deleted).
+	SetScriptSharedWithAllTeams(ctx context.Context, scriptID uint, shared bool) error
+
+	// SetMDMConfigProfileSharedWithAllTeams shares the "no team" Apple or
+	// Windows configuration profile with all teams, or stops sharing it (in
+	// which case the opt-outs of the teams are deleted).
+	SetMDMConfigProfileSharedWithAllTeams(ctx context.Context, profileUUID string, shared bool) error
+
+	// GetTeamSharedLibraryOptOuts returns the shared scripts and profiles that
+	// the team opted out of.
+	GetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint) (*TeamSharedLibraryOptOuts, error)
+
+	// SetTeamSharedLibraryOptOuts replaces the shared scripts and profiles that
+	// the team opted out of. It fails if one of them is not shared with all
+	// teams.
+	SetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint, optOuts TeamSharedLibraryOptOuts) error
+
+	// NewScriptSchedule creates a schedule to run a saved script every day at a
+	// local time of the hosts.
+	NewScriptSchedule(ctx context.Context, schedule *ScriptSchedule) (*ScriptSchedule, error)
+
+	// ScriptSchedule returns the script schedule corresponding to id.
+	ScriptSchedule(ctx context.Context, id uint) (*ScriptSchedule, error)
+
+	// ListScriptSchedules returns the schedules of the scripts of the given team
+	// (or no team if teamID is nil).
+	ListScriptSchedules(ctx context.Context, teamID *uint) ([]*ScriptSchedule, error)
+
+	// DeleteScriptSchedule deletes the script schedule corresponding to id.
+	DeleteScriptSchedule(ctx context.Context, id uint) error
+
+	// ListScriptScheduleHosts returns the hosts targeted by each script
+	// schedule, along with their timezone and the last local date on which the
+	// schedule queued the script on the host.
+	ListScriptScheduleHosts(ctx context.Context) ([]*ScriptScheduleHost, error)
+
+	// NewScheduledHostScriptExecutionRequest queues the execution of a saved
+	// script on a host for a script schedule, and records that the schedule ran
+	// on the host on localDate.
+	NewScheduledHostScriptExecutionRequest(ctx context.Context, request *HostScriptRequestPayload, scheduleID uint, localDate string) (*HostScriptResult, error)
+
 	// GetHostLockWipeStatus gets the lock/unlock and wipe status for the host.
 	GetHostLockWipeStatus(ctx context.Context, host *Host) (*HostLockWipeStatus, error)
 
@@ -1430,6 +2342,22 @@ type Datastore interface {
 	// after it has been unlocked.
 	CleanMacOSMDMLock(ctx context.Context, hostUUID string) error
 
+	// NewHostLockWipeApprovalRequest records a lock or wipe request of a host
+	// that must be approved by a second user. It replaces any existing request
+	// for the same host and action.
+	NewHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string, userID uint) error
+
+	// GetHostLockWipeApprovalRequest returns the lock or wipe request of a host
+	// that is waiting for approval. It returns a not found error if there is
+	// none.
+	GetHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string) (*HostLockWipeApprovalRequest, error)
+
+	// ClaimHostLockWipeApprovalRequest deletes the lock or wipe request of a
+	// host that is waiting for approval, if it is still the request returned by
+	// GetHostLockWipeApprovalRequest. It returns true only for the caller that
+	// deleted it, so that a request approved concurrently is acted upon once.
+	ClaimHostLockWipeApprovalRequest(ctx context.Context, req *HostLockWipeApprovalRequest) (bool, error)
+
 	// CleanupUnusedScriptContents will remove script contents that have no references to them from
 	// the scripts or host_script_results tables.
 	CleanupUnusedScriptContents(ctx context.Context) error
@@ -1442,6 +2370,14 @@ type Datastore interface {
 	// states in host_mdm_actions.
 	WipeHostViaScript(ctx context.Context, request *HostScriptRequestPayload, hostFleetPlatform string) error
 
+	// IsolateHostViaScript sends a script to isolate a host from the network
+	// and updates the states in host_mdm_actions.
+	IsolateHostViaScript(ctx context.Context, request *HostScriptRequestPayload, hostFleetPlatform string) error
+
+	// UnisolateHostViaScript sends a script to remove the network isolation of
+	// a host and updates the states in host_mdm_actions.
+	UnisolateHostViaScript(ctx context.Context, request *HostScriptRequestPayload, hostFleetPlatform string) error
+
 	// WipeHostViaWindowsMDM sends a Windows MDM command to wipe a host and
 	// updates the states in host_mdm_actions.
 	WipeHostViaWindowsMDM(ctx context.Context, host *Host, cmd *MDMWindowsCommand) error
@@ -1459,6 +2395,17 @@ type MDMAppleStore interface {
 	storage.AllStorage
 	EnqueueDeviceLockCommand(ctx context.Context, host *Host, cmd *mdm.Command, pin string) error
 	EnqueueDeviceWipeCommand(ctx context.Context, host *Host, cmd *mdm.Command) error
+	// EnqueueSetFirmwarePasswordCommand enqueues a SetFirmwarePassword command
+	// for the host and records its new password, encrypted, as pending until
+	// the host acknowledges the command.
+	EnqueueSetFirmwarePasswordCommand(ctx context.Context, host *Host, cmd *mdm.Command, newPassword string) error
+	// EnabledEnrollmentIDs returns the subset of the provided enrollment ids
+	// (host UUIDs for device enrollments) that exist and are enabled.
+	EnabledEnrollmentIDs(ctx context.Context, ids []string) ([]string, error)
+	// EnqueueStoredCommand enqueues a command that was already stored by a
+	// previous call to EnqueueCommand for the provided enrollment ids, it is
+	// used to enqueue a command in batches.
+	EnqueueStoredCommand(ctx context.Context, ids []string, commandUUID string) error
 }
 
 // Cloner represents any type that can clone itself. Used for the cached_mysql
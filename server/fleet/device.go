@@ -23,6 +23,11 @@ type DesktopNotifications struct {
 type DesktopConfig struct {
 	OrgInfo DesktopOrgInfo   `json:"org_info,omitempty"`
 	MDM     DesktopMDMConfig `json:"mdm"`
+	// Locale is the locale of the end-user facing messages of the host.
+	Locale string `json:"locale,omitempty"`
+	// Messages are the translated end-user facing messages for the locale,
+	// the messages that are not translated fall back to the defaults.
+	Messages LocalizedMessages `json:"messages,omitempty"`
 }
 
 // DesktopMDMConfig is a subset of fleet.MDM with configuration that's relevant
@@ -40,6 +45,8 @@ type DesktopOrgInfo struct {
 	OrgLogoURL                string `json:"org_logo_url"`
 	OrgLogoURLLightBackground string `json:"org_logo_url_light_background"`
 	ContactURL                string `json:"contact_url"`
+	PrimaryColor              string `json:"primary_color,omitempty"`
+	BackgroundColor           string `json:"background_color,omitempty"`
 }
 
 type MigrateMDMDeviceWebhookPayload struct {
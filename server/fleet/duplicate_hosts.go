@@ -0,0 +1,139 @@
+package fleet
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// DuplicateHostsSettings contains the settings of the detection and merge of
+// duplicate hosts.
+type DuplicateHostsSettings struct {
+	// AutoMerge enables the cron job that merges the duplicate hosts into the
+	// most recently seen host of each set of duplicates.
+	AutoMerge bool `json:"auto_merge"`
+}
+
+// genericHardwareSerials are serial numbers reported by virtual machines and
+// some hardware vendors that are not unique, they cannot be used to identify
+// duplicate hosts.
+var genericHardwareSerials = map[string]bool{
+	"0":                      true,
+	"0123456789":             true,
+	"chassis serial number":  true,
+	"default string":         true,
+	"n/a":                    true,
+	"none":                   true,
+	"not applicable":         true,
+	"not specified":          true,
+	"system serial number":   true,
+	"to be filled by o.e.m.": true,
+	"unknown":                true,
+}
+
+// IsGenericHardwareSerial returns true if the hardware serial number is empty
+// or is a placeholder value that is not unique to a host.
+func IsGenericHardwareSerial(serial string) bool {
+	serial = strings.ToLower(strings.TrimSpace(serial))
+	return serial == "" || genericHardwareSerials[serial]
+}
+
+// DuplicateHost is a host that has the same hardware serial number or
+// hardware UUID as another host, typically because it was re-imaged and
+// enrolled again as a new host.
+type DuplicateHost struct {
+	ID             uint      `json:"id" db:"id"`
+	Hostname       string    `json:"hostname" db:"hostname"`
+	ComputerName   string    `json:"computer_name" db:"computer_name"`
+	HardwareSerial string    `json:"hardware_serial" db:"hardware_serial"`
+	UUID           string    `json:"uuid" db:"uuid"`
+	Platform       string    `json:"platform" db:"platform"`
+	TeamID         *uint     `json:"team_id" db:"team_id"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	SeenTime       time.Time `json:"seen_time" db:"seen_time"`
+}
+
+// DuplicateHostSet is a set of hosts that are duplicates of each other.
+type DuplicateHostSet struct {
+	// Hosts are the duplicate hosts, the most recently seen first. That host is
+	// the one that is kept when the set is merged automatically.
+	Hosts []*DuplicateHost `json:"hosts"`
+}
+
+// IsDuplicateHost returns true if the hosts have the same hardware UUID or
+// the same (non-generic) hardware serial number.
+func IsDuplicateHost(h1, h2 *Host) bool {
+	if h1.UUID != "" && strings.EqualFold(h1.UUID, h2.UUID) {
+		return true
+	}
+	return !IsGenericHardwareSerial(h1.HardwareSerial) && strings.EqualFold(h1.HardwareSerial, h2.HardwareSerial)
+}
+
+// GroupDuplicateHosts groups the hosts into sets of duplicates, hosts are
+// duplicates if they share a hardware UUID or a (non-generic) hardware serial
+// number, directly or through another host of the set. Hosts that have no
+// duplicate are ignored.
+func GroupDuplicateHosts(hosts []*DuplicateHost) []*DuplicateHostSet {
+	// union-find of the hosts' indexes
+	parent := make([]int, len(hosts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	bySerial := make(map[string]int)
+	byUUID := make(map[string]int)
+	for i, h := range hosts {
+		if !IsGenericHardwareSerial(h.HardwareSerial) {
+			key := strings.ToLower(h.HardwareSerial)
+			if j, ok := bySerial[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				bySerial[key] = i
+			}
+		}
+		if h.UUID != "" {
+			key := strings.ToLower(h.UUID)
+			if j, ok := byUUID[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				byUUID[key] = i
+			}
+		}
+	}
+
+	setsByRoot := make(map[int]*DuplicateHostSet)
+	var sets []*DuplicateHostSet
+	for i, h := range hosts {
+		root := find(i)
+		set := setsByRoot[root]
+		if set == nil {
+			set = &DuplicateHostSet{}
+			setsByRoot[root] = set
+			sets = append(sets, set)
+		}
+		set.Hosts = append(set.Hosts, h)
+	}
+
+	res := make([]*DuplicateHostSet, 0, len(sets))
+	for _, set := range sets {
+		if len(set.Hosts) < 2 {
+			continue
+		}
+		sort.SliceStable(set.Hosts, func(i, j int) bool {
+			hi, hj := set.Hosts[i], set.Hosts[j]
+			if !hi.SeenTime.Equal(hj.SeenTime) {
+				return hi.SeenTime.After(hj.SeenTime)
+			}
+			return hi.ID > hj.ID
+		})
+		res = append(res, set)
+	}
+	return res
+}
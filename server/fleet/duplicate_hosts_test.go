@@ -0,0 +1,67 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGenericHardwareSerial(t *testing.T) {
+	for _, serial := range []string{"", " ", "0", "Not Specified", "System Serial Number", "Default string", "To be filled by O.E.M."} {
+		require.True(t, IsGenericHardwareSerial(serial), serial)
+	}
+	for _, serial := range []string{"C02XL0GHJGH5", "VMware-56 4d 2e"} {
+		require.False(t, IsGenericHardwareSerial(serial), serial)
+	}
+}
+
+func TestIsDuplicateHost(t *testing.T) {
+	cases := []struct {
+		desc   string
+		h1, h2 *Host
+		want   bool
+	}{
+		{"same serial", &Host{HardwareSerial: "ABC"}, &Host{HardwareSerial: "abc"}, true},
+		{"same uuid", &Host{UUID: "U1", HardwareSerial: "A"}, &Host{UUID: "u1", HardwareSerial: "B"}, true},
+		{"different", &Host{UUID: "U1", HardwareSerial: "A"}, &Host{UUID: "U2", HardwareSerial: "B"}, false},
+		{"generic serial", &Host{HardwareSerial: "0"}, &Host{HardwareSerial: "0"}, false},
+		{"empty uuid", &Host{}, &Host{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, IsDuplicateHost(c.h1, c.h2))
+		})
+	}
+}
+
+func TestGroupDuplicateHosts(t *testing.T) {
+	now := time.Now()
+	hosts := []*DuplicateHost{
+		{ID: 1, HardwareSerial: "S1", UUID: "U1", SeenTime: now.Add(-2 * time.Hour)},
+		{ID: 2, HardwareSerial: "S1", UUID: "U2", SeenTime: now},
+		// linked to host 2 by uuid only
+		{ID: 3, HardwareSerial: "S3", UUID: "U2", SeenTime: now.Add(-time.Hour)},
+		// generic serial, not a duplicate
+		{ID: 4, HardwareSerial: "0", UUID: "U4", SeenTime: now},
+		{ID: 5, HardwareSerial: "0", UUID: "U5", SeenTime: now},
+		// duplicates with the same seen time, highest ID first
+		{ID: 6, HardwareSerial: "S6", SeenTime: now},
+		{ID: 7, HardwareSerial: "S6", SeenTime: now},
+	}
+
+	sets := GroupDuplicateHosts(hosts)
+	require.Len(t, sets, 2)
+
+	var ids [][]uint
+	for _, set := range sets {
+		var setIDs []uint
+		for _, h := range set.Hosts {
+			setIDs = append(setIDs, h.ID)
+		}
+		ids = append(ids, setIDs)
+	}
+	require.Equal(t, [][]uint{{2, 3, 1}, {7, 6}}, ids)
+
+	require.Empty(t, GroupDuplicateHosts(nil))
+}
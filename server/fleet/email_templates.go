@@ -0,0 +1,139 @@
+package fleet
+
+import (
+	"strings"
+	"time"
+)
+
+// EmailTemplateName identifies an email sent by Fleet whose template can be
+// customized.
+type EmailTemplateName string
+
+const (
+	EmailTemplateInvite        EmailTemplateName = "invite"
+	EmailTemplatePasswordReset EmailTemplateName = "password_reset"
+	EmailTemplateMDMMigration  EmailTemplateName = "mdm_migration"
+	// EmailTemplateLockWipeNotification is sent to the end user of a host
+	// when it is locked, unlocked or wiped.
+	EmailTemplateLockWipeNotification EmailTemplateName = "lock_wipe_notification"
+)
+
+// EmailTemplateNames lists the emails whose template can be customized.
+var EmailTemplateNames = []EmailTemplateName{
+	EmailTemplateInvite,
+	EmailTemplatePasswordReset,
+	EmailTemplateMDMMigration,
+	EmailTemplateLockWipeNotification,
+}
+
+var emailTemplateDefaultSubjects = map[EmailTemplateName]string{
+	EmailTemplateInvite:               "You are Invited to Fleet",
+	EmailTemplatePasswordReset:        "Reset Your Fleet Password",
+	EmailTemplateMDMMigration:         "Migrate your Mac to Fleet",
+	EmailTemplateLockWipeNotification: "An action was taken on your device",
+}
+
+// IsValid returns true if n is the name of an email whose template can be
+// customized.
+func (n EmailTemplateName) IsValid() bool {
+	_, ok := emailTemplateDefaultSubjects[n]
+	return ok
+}
+
+// DefaultSubject returns the subject of the email if it is not customized.
+func (n EmailTemplateName) DefaultSubject() string {
+	return emailTemplateDefaultSubjects[n]
+}
+
+// EmailTemplate is the customization of an email sent by Fleet. Empty fields
+// fall back to the compiled-in defaults: the default subject, the default HTML
+// template and the sender address of the SMTP settings.
+type EmailTemplate struct {
+	Name EmailTemplateName `json:"name" db:"name"`
+	// Subject is the subject of the email.
+	Subject string `json:"subject" db:"subject"`
+	// Body is the HTML template of the email, in Go's html/template syntax.
+	Body string `json:"body" db:"body"`
+	// SenderName is the display name of the sender of the email.
+	SenderName string `json:"sender_name" db:"sender_name"`
+	// SenderAddress is the email address the email is sent from.
+	SenderAddress string `json:"sender_address" db:"sender_address"`
+
+	// Custom is true if the email template has been customized.
+	Custom bool `json:"custom" db:"-"`
+	// Variables are the names of the variables available to the HTML
+	// template of the email.
+	Variables []string `json:"variables,omitempty" db:"-"`
+
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ApplyTo sets the subject and sender identity of the email from the email
+// template, falling back to the defaults for the empty fields.
+func (t EmailTemplate) ApplyTo(e *Email) {
+	e.Subject = t.Subject
+	if e.Subject == "" {
+		e.Subject = t.Name.DefaultSubject()
+	}
+	e.SenderName = t.SenderName
+	e.SenderAddress = t.SenderAddress
+}
+
+// EmailTemplatePayload is the payload to customize an email template. Nil
+// fields are left unchanged, empty strings reset the field to its default.
+type EmailTemplatePayload struct {
+	Subject       *string `json:"subject"`
+	Body          *string `json:"body"`
+	SenderName    *string `json:"sender_name"`
+	SenderAddress *string `json:"sender_address"`
+}
+
+// Apply returns a copy of the email template with the payload's changes
+// applied.
+func (p EmailTemplatePayload) Apply(tmpl EmailTemplate) EmailTemplate {
+	if p.Subject != nil {
+		tmpl.Subject = strings.TrimSpace(*p.Subject)
+	}
+	if p.Body != nil {
+		tmpl.Body = *p.Body
+	}
+	if p.SenderName != nil {
+		tmpl.SenderName = strings.TrimSpace(*p.SenderName)
+	}
+	if p.SenderAddress != nil {
+		tmpl.SenderAddress = strings.TrimSpace(*p.SenderAddress)
+	}
+	return tmpl
+}
+
+// Validate validates the fields of the email template that are written to
+// the email headers. The HTML template is validated by the mail package.
+func (t EmailTemplate) Validate() error {
+	invalid := &InvalidArgumentError{}
+	if len(t.Subject) > 255 {
+		invalid.Append("subject", "must be 255 characters or less")
+	}
+	if strings.ContainsAny(t.Subject, "\r\n") {
+		invalid.Append("subject", "must not contain line breaks")
+	}
+	if len(t.SenderName) > 255 {
+		invalid.Append("sender_name", "must be 255 characters or less")
+	}
+	if strings.ContainsAny(t.SenderName, "\r\n") {
+		invalid.Append("sender_name", "must not contain line breaks")
+	}
+	if t.SenderAddress != "" && (!IsLooseEmail(t.SenderAddress) || strings.ContainsAny(t.SenderAddress, "\r\n<>")) {
+		invalid.Append("sender_address", "must be a valid email address")
+	}
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
+
+// EmailTemplatePreview is an email template rendered with sample data.
+type EmailTemplatePreview struct {
+	Subject string `json:"subject"`
+	From    string `json:"from"`
+	HTML    string `json:"html"`
+}
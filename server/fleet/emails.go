@@ -18,6 +18,19 @@ type Email struct {
 	ServerURL    string
 	SMTPSettings SMTPSettings
 	Mailer       Mailer
+	// SenderName and SenderAddress override the sender identity of the
+	// email, they come from the email template customizations.
+	SenderName    string
+	SenderAddress string
+}
+
+// FromAddress returns the address the email is sent from, the sender
+// address of the SMTP settings unless it is overridden.
+func (e Email) FromAddress() string {
+	if e.SenderAddress != "" {
+		return e.SenderAddress
+	}
+	return e.SMTPSettings.SMTPSenderAddress
 }
 
 type MailService interface {
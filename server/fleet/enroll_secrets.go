@@ -0,0 +1,88 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MaxEnrollSecretLabels is the maximum number of labels that the hosts are
+// added to when they enroll with an enroll secret.
+const MaxEnrollSecretLabels = 20
+
+// ValidateEnrollSecretHostRules checks that the labels of the enroll secrets
+// are existing manual labels and that their custom fields are defined in the
+// app config with valid values. The values of the custom fields are
+// normalized.
+func ValidateEnrollSecretHostRules(ctx context.Context, ds Datastore, secrets []*EnrollSecret) error {
+	invalid := &InvalidArgumentError{}
+
+	var (
+		defs       []HostCustomFieldDefinition
+		defsLoaded bool
+	)
+	labels := make(map[string]*LabelSpec)
+	for i, s := range secrets {
+		key := fmt.Sprintf("secrets[%d]", i)
+
+		if len(s.Labels) > MaxEnrollSecretLabels {
+			invalid.Append(key+".labels", fmt.Sprintf("cannot have more than %d labels", MaxEnrollSecretLabels))
+		}
+		seen := make(map[string]bool, len(s.Labels))
+		for _, name := range s.Labels {
+			if seen[name] {
+				invalid.Append(key+".labels", fmt.Sprintf("duplicate label %q", name))
+				continue
+			}
+			seen[name] = true
+
+			spec, ok := labels[name]
+			if !ok {
+				var err error
+				spec, err = ds.GetLabelSpec(ctx, name)
+				if err != nil {
+					if !IsNotFound(err) {
+						return fmt.Errorf("get label %q: %w", name, err)
+					}
+					spec = nil
+				}
+				labels[name] = spec
+			}
+			switch {
+			case spec == nil:
+				invalid.Append(key+".labels", fmt.Sprintf("label %q does not exist", name))
+			case spec.LabelType == LabelTypeBuiltIn || spec.LabelMembershipType != LabelMembershipTypeManual:
+				invalid.Append(key+".labels", fmt.Sprintf("label %q is not a manual label", name))
+			}
+		}
+
+		if len(s.CustomFields) == 0 {
+			continue
+		}
+		if !defsLoaded {
+			appConfig, err := ds.AppConfig(ctx)
+			if err != nil {
+				return fmt.Errorf("get app config: %w", err)
+			}
+			defs, defsLoaded = appConfig.HostCustomFields, true
+		}
+		for name, value := range s.CustomFields {
+			def, ok := HostCustomFieldDefinitionByName(defs, name)
+			if !ok {
+				invalid.Append(key+".custom_fields", fmt.Sprintf("custom field %q is not defined", name))
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if err := def.ValidateValue(value); err != nil {
+				invalid.Append(key+".custom_fields", err.Error())
+				continue
+			}
+			s.CustomFields[name] = value
+		}
+	}
+
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 var (
 	ErrNoContext             = errors.New("context key not set")
 	ErrPasswordResetRequired = &passwordResetRequiredError{}
+	ErrMFAEnrollmentRequired = &mfaEnrollmentRequiredError{}
 	ErrMissingLicense        = &licenseError{}
 	ErrMDMNotConfigured      = &MDMNotConfiguredError{}
 
@@ -303,6 +304,18 @@ func (e passwordResetRequiredError) StatusCode() int {
 	return http.StatusUnauthorized
 }
 
+type mfaEnrollmentRequiredError struct {
+	ErrorWithUUID
+}
+
+func (e mfaEnrollmentRequiredError) Error() string {
+	return "multi-factor authentication enrollment required"
+}
+
+func (e mfaEnrollmentRequiredError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
 // MDMNotConfiguredError is used when an MDM endpoint or resource is accessed
 // without having MDM correctly configured.
 type MDMNotConfiguredError struct{}
@@ -551,6 +564,22 @@ const (
 	RunScripSavedMaxLenErrMsg              = "Script is too large. It's limited to 500,000 characters (approximately 10,000 lines)."
 	RunScripUnsavedMaxLenErrMsg            = "Script is too large. It's limited to 10,000 characters (approximately 125 lines)."
 
+	// Host log collection
+	HostLogCollectionFleetdRequiredErrMsg = "Couldn't collect logs. To collect logs, deploy the fleetd agent."
+	HostLogCollectionOrbitDisabledErrMsg  = "Couldn't collect logs. To collect logs, deploy the fleetd agent with --enable-scripts."
+
+	// Host file retrieval
+	HostFileRetrievalFleetdRequiredErrMsg = "Couldn't retrieve file. To retrieve files, deploy the fleetd agent."
+	HostFileRetrievalOrbitDisabledErrMsg  = "Couldn't retrieve file. To retrieve files, deploy the fleetd agent with --enable-scripts."
+	HostFileRetrievalPathNotAllowedErrMsg = "Couldn't retrieve file. The path is not allowed by the file_retrieval.allowed_paths setting of the host's team (or the global setting for hosts with no team)."
+
+	// Host pprof capture
+	HostPprofCaptureFleetdRequiredErrMsg = "Couldn't capture profile. To capture a profile, deploy the fleetd agent."
+
+	// Host remote query
+	HostRemoteQueryFleetdRequiredErrMsg = "Couldn't run query. To run a remote query, deploy the fleetd agent."
+	HostRemoteQueryDisabledErrMsg       = "Couldn't run query. Remote queries are disabled by the remote_queries.enabled setting of the host's team (or the global setting for hosts with no team)."
+
 	// End user authentication
 	EndUserAuthDEPWebURLConfiguredErrMsg = `End user authentication can't be configured when the configured automatic enrollment (DEP) profile specifies a configuration_web_url.` // #nosec G101
 )
@@ -0,0 +1,61 @@
+package fleet
+
+import (
+	"net/http"
+)
+
+// GitOpsModeSettings contains the settings of the GitOps mode.
+type GitOpsModeSettings struct {
+	// GitOpsModeEnabled makes the configuration read-only for the users that
+	// are not allowed to apply GitOps (see User.CanApplyInGitOpsMode), so that
+	// the configuration can only change via the GitOps repository and does not
+	// drift from it.
+	GitOpsModeEnabled bool `json:"gitops_mode_enabled"`
+	// RepositoryURL is the URL of the GitOps repository, it is returned in the
+	// errors of the rejected configuration changes. It is required to enable
+	// GitOps mode.
+	RepositoryURL string `json:"repository_url"`
+}
+
+// CanApplyInGitOpsMode returns true if the user is allowed to change the
+// configuration when GitOps mode is enabled, which is the case of API-only
+// users and of users with the GitOps role (globally or in a team). Interactive
+// users (i.e. of the UI) are not allowed.
+func (u *User) CanApplyInGitOpsMode() bool {
+	if u == nil {
+		return false
+	}
+	if u.APIOnly {
+		return true
+	}
+	if u.GlobalRole != nil && *u.GlobalRole == RoleGitOps {
+		return true
+	}
+	for _, t := range u.Teams {
+		if t.Role == RoleGitOps {
+			return true
+		}
+	}
+	return false
+}
+
+// GitOpsModeError is returned when a change to the configuration is rejected
+// because GitOps mode is enabled.
+type GitOpsModeError struct {
+	RepositoryURL string
+
+	ErrorWithUUID
+}
+
+func (e *GitOpsModeError) Error() string {
+	msg := "GitOps mode is enabled, the configuration is managed in the GitOps repository"
+	if e.RepositoryURL != "" {
+		msg += " (" + e.RepositoryURL + ")"
+	}
+	return msg + ". Make the change in the repository instead, it will be applied by GitOps."
+}
+
+// StatusCode implements the kithttp.StatusCoder interface.
+func (e *GitOpsModeError) StatusCode() int {
+	return http.StatusForbidden
+}
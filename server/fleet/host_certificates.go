@@ -0,0 +1,72 @@
+package fleet
+
+import "time"
+
+// HostCertificateOrigin is how a host certificate was reported to Fleet.
+type HostCertificateOrigin string
+
+const (
+	// HostCertificateOriginOsquery is for the certificates reported by
+	// osquery's certificates table.
+	HostCertificateOriginOsquery HostCertificateOrigin = "osquery"
+	// HostCertificateOriginMDM is for the certificates reported by the Apple
+	// MDM CertificateList command.
+	HostCertificateOriginMDM HostCertificateOrigin = "mdm"
+)
+
+// HostCertificate is a certificate found in a host's keychains (macOS) or
+// certificate stores (Windows), as reported by osquery's certificates table
+// or by the Apple MDM CertificateList command.
+type HostCertificate struct {
+	ID     uint `json:"-" db:"id"`
+	HostID uint `json:"-" db:"host_id"`
+	// SHA1 is the lowercase hex-encoded SHA-1 fingerprint of the certificate,
+	// it identifies the certificate across hosts.
+	SHA1           string    `json:"sha1" db:"sha1"`
+	CommonName     string    `json:"common_name" db:"common_name"`
+	Subject        string    `json:"subject" db:"subject"`
+	Issuer         string    `json:"issuer" db:"issuer"`
+	CA             bool      `json:"ca" db:"ca"`
+	SelfSigned     bool      `json:"self_signed" db:"self_signed"`
+	NotValidBefore time.Time `json:"not_valid_before" db:"not_valid_before"`
+	NotValidAfter  time.Time `json:"not_valid_after" db:"not_valid_after"`
+	// Source is the keychain path or the certificate store where the
+	// certificate was found.
+	Source string `json:"source" db:"source"`
+	// Origin is how the certificate was reported.
+	Origin HostCertificateOrigin `json:"origin" db:"origin"`
+	// IsIdentity is true if the certificate has a private key on the host,
+	// e.g. a SCEP identity. It is only reported by MDM.
+	IsIdentity bool `json:"is_identity" db:"is_identity"`
+}
+
+// CertificateInventoryItem is a certificate aggregated across the hosts on
+// which it was found.
+type CertificateInventoryItem struct {
+	SHA1           string    `json:"sha1" db:"sha1"`
+	CommonName     string    `json:"common_name" db:"common_name"`
+	Subject        string    `json:"subject" db:"subject"`
+	Issuer         string    `json:"issuer" db:"issuer"`
+	CA             bool      `json:"ca" db:"ca"`
+	SelfSigned     bool      `json:"self_signed" db:"self_signed"`
+	NotValidBefore time.Time `json:"not_valid_before" db:"not_valid_before"`
+	NotValidAfter  time.Time `json:"not_valid_after" db:"not_valid_after"`
+	HostsCount     uint      `json:"hosts_count" db:"hosts_count"`
+}
+
+// CertificateInventoryListOptions are the options to list the certificate
+// inventory.
+type CertificateInventoryListOptions struct {
+	// ListOptions cannot be embedded in order to unmarshall with validation.
+	ListOptions ListOptions `url:"list_options"`
+
+	// TeamID filters the certificates found on the hosts of that team, 0 is
+	// for hosts in no team.
+	TeamID *uint `query:"team_id,optional"`
+	// ExpiringWithinDays filters the certificates that expire within that
+	// number of days (including the ones already expired).
+	ExpiringWithinDays *uint `query:"expiring_within_days,optional"`
+	// CA filters the certificate authorities (or the non-CA certificates if
+	// false).
+	CA *bool `query:"ca,optional"`
+}
@@ -0,0 +1,86 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// CloudProvider is a cloud provider whose instance metadata is reported by
+// fleetd's cloud_instance_metadata table.
+type CloudProvider string
+
+const (
+	CloudProviderAWS   CloudProvider = "aws"
+	CloudProviderGCP   CloudProvider = "gcp"
+	CloudProviderAzure CloudProvider = "azure"
+)
+
+// IsValid returns true if p is a supported cloud provider.
+func (p CloudProvider) IsValid() bool {
+	switch p {
+	case CloudProviderAWS, CloudProviderGCP, CloudProviderAzure:
+		return true
+	default:
+		return false
+	}
+}
+
+// HostCloudInstance is the cloud instance that a host runs on, as reported
+// by the instance metadata service of the cloud provider.
+type HostCloudInstance struct {
+	HostID   uint          `json:"-" db:"host_id"`
+	Provider CloudProvider `json:"provider" db:"provider"`
+	// InstanceID is the ID of the instance (the VM ID on Azure).
+	InstanceID string `json:"instance_id" db:"instance_id"`
+	Region     string `json:"region" db:"region"`
+	// AccountID is the AWS account ID, Google Cloud project ID or Azure
+	// subscription ID that owns the instance.
+	AccountID string            `json:"account_id" db:"account_id"`
+	Tags      CloudInstanceTags `json:"tags" db:"tags"`
+}
+
+// CloudInstanceTags are the tags of a cloud instance. They are stored as a
+// JSON object and rendered as "key=value" pairs separated by semicolons in
+// the CSV exports.
+type CloudInstanceTags map[string]string
+
+// Scan implements the sql.Scanner interface.
+func (t *CloudInstanceTags) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, t)
+	case string:
+		return json.Unmarshal([]byte(src), t)
+	default:
+		return errors.New("unsupported type for cloud instance tags")
+	}
+}
+
+// Value implements the driver.Valuer interface.
+func (t CloudInstanceTags) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(t)
+}
+
+// MarshalCSV implements the gocsv.TypeMarshaller interface.
+func (t CloudInstanceTags) MarshalCSV() (string, error) {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+t[k])
+	}
+	return strings.Join(pairs, "; "), nil
+}
@@ -0,0 +1,40 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudInstanceTags(t *testing.T) {
+	var tags CloudInstanceTags
+	require.NoError(t, tags.Scan([]byte(`{"env": "prod", "Name": "web-1"}`)))
+	require.Equal(t, CloudInstanceTags{"env": "prod", "Name": "web-1"}, tags)
+
+	s, err := tags.MarshalCSV()
+	require.NoError(t, err)
+	require.Equal(t, "Name=web-1; env=prod", s)
+
+	v, err := tags.Value()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"env": "prod", "Name": "web-1"}`, string(v.([]byte)))
+
+	require.NoError(t, tags.Scan(nil))
+	require.Nil(t, tags)
+	s, err = tags.MarshalCSV()
+	require.NoError(t, err)
+	require.Empty(t, s)
+	v, err = tags.Value()
+	require.NoError(t, err)
+	require.Equal(t, []byte("{}"), v)
+
+	require.Error(t, tags.Scan(42))
+}
+
+func TestCloudProviderIsValid(t *testing.T) {
+	for _, p := range []CloudProvider{CloudProviderAWS, CloudProviderGCP, CloudProviderAzure} {
+		require.True(t, p.IsValid())
+	}
+	require.False(t, CloudProvider("oracle").IsValid())
+	require.False(t, CloudProvider("").IsValid())
+}
@@ -0,0 +1,188 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HostCustomFieldsKind = "host_custom_fields"
+)
+
+// HostCustomFieldType is the type of the values of a host custom field.
+type HostCustomFieldType string
+
+const (
+	HostCustomFieldTypeString HostCustomFieldType = "string"
+	HostCustomFieldTypeNumber HostCustomFieldType = "number"
+	// HostCustomFieldTypeDate is a date in the YYYY-MM-DD format.
+	HostCustomFieldTypeDate HostCustomFieldType = "date"
+)
+
+// IsValid returns true if t is a known custom field type.
+func (t HostCustomFieldType) IsValid() bool {
+	switch t {
+	case HostCustomFieldTypeString, HostCustomFieldTypeNumber, HostCustomFieldTypeDate:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// MaxHostCustomFieldNameLength is the maximum length of the name of a
+	// host custom field.
+	MaxHostCustomFieldNameLength = 64
+	// MaxHostCustomFieldValueLength is the maximum length of the value of a
+	// host custom field.
+	MaxHostCustomFieldValueLength = 255
+	// MaxHostNotesLength is the maximum length of the notes of a host.
+	MaxHostNotesLength = 10000
+)
+
+var hostCustomFieldNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// HostCustomFieldDefinition defines a custom field that can be set on hosts,
+// e.g. the owner, asset tag or cost center of the host.
+type HostCustomFieldDefinition struct {
+	// Name is the unique name of the field, in snake_case.
+	Name string              `json:"name"`
+	Type HostCustomFieldType `json:"type"`
+}
+
+// ValidateHostCustomFieldDefinitions validates the custom field definitions
+// of the app config and records any error in invalid.
+func ValidateHostCustomFieldDefinitions(defs []HostCustomFieldDefinition, invalid *InvalidArgumentError) {
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		key := fmt.Sprintf("host_custom_fields[%d]", i)
+		switch {
+		case def.Name == "":
+			invalid.Append(key+".name", "name is required")
+		case len(def.Name) > MaxHostCustomFieldNameLength:
+			invalid.Append(key+".name", fmt.Sprintf("name cannot be longer than %d characters", MaxHostCustomFieldNameLength))
+		case !hostCustomFieldNameRegexp.MatchString(def.Name):
+			invalid.Append(key+".name", "name must start with a letter and contain only lowercase letters, digits and underscores")
+		case seen[def.Name]:
+			invalid.Append(key+".name", fmt.Sprintf("duplicate name %q", def.Name))
+		}
+		seen[def.Name] = true
+
+		if !def.Type.IsValid() {
+			invalid.Append(key+".type", fmt.Sprintf("invalid type %q, must be one of %q, %q or %q", def.Type,
+				HostCustomFieldTypeString, HostCustomFieldTypeNumber, HostCustomFieldTypeDate))
+		}
+	}
+}
+
+// ValidateValue checks that value is valid for the type of the field.
+func (d HostCustomFieldDefinition) ValidateValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("the value of %q cannot be empty", d.Name)
+	}
+	if len(value) > MaxHostCustomFieldValueLength {
+		return fmt.Errorf("the value of %q cannot be longer than %d characters", d.Name, MaxHostCustomFieldValueLength)
+	}
+
+	switch d.Type {
+	case HostCustomFieldTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("the value of %q must be a number", d.Name)
+		}
+	case HostCustomFieldTypeDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("the value of %q must be a date in the YYYY-MM-DD format", d.Name)
+		}
+	}
+	return nil
+}
+
+// HostCustomFieldDefinitionByName returns the definition of the custom field
+// with the provided name, or false if it is not defined.
+func HostCustomFieldDefinitionByName(defs []HostCustomFieldDefinition, name string) (HostCustomFieldDefinition, bool) {
+	for _, def := range defs {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return HostCustomFieldDefinition{}, false
+}
+
+// HostCustomFieldValues maps the name of the custom fields to their value for
+// a host.
+type HostCustomFieldValues map[string]string
+
+// Scan implements the sql.Scanner interface, the values are loaded as a JSON
+// object.
+func (v *HostCustomFieldValues) Scan(val interface{}) error {
+	switch tv := val.(type) {
+	case []byte:
+		return json.Unmarshal(tv, v)
+	case string:
+		return json.Unmarshal([]byte(tv), v)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", tv)
+	}
+}
+
+// HostCustomFieldsPayload is the payload used to update the notes and custom
+// fields of a host. A nil Notes leaves the notes unchanged, an empty one
+// clears them. Only the custom fields present in CustomFields are updated, a
+// nil value clears the field.
+type HostCustomFieldsPayload struct {
+	Notes        *string            `json:"notes"`
+	CustomFields map[string]*string `json:"custom_fields"`
+}
+
+// Validate checks the payload against the custom field definitions and
+// normalizes the values.
+func (p *HostCustomFieldsPayload) Validate(defs []HostCustomFieldDefinition) error {
+	invalid := &InvalidArgumentError{}
+	if p.Notes != nil {
+		notes := strings.TrimSpace(*p.Notes)
+		if len(notes) > MaxHostNotesLength {
+			invalid.Append("notes", fmt.Sprintf("notes cannot be longer than %d characters", MaxHostNotesLength))
+		}
+		p.Notes = &notes
+	}
+	for name, value := range p.CustomFields {
+		def, ok := HostCustomFieldDefinitionByName(defs, name)
+		if !ok {
+			invalid.Append("custom_fields", fmt.Sprintf("custom field %q is not defined", name))
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		v := strings.TrimSpace(*value)
+		if err := def.ValidateValue(v); err != nil {
+			invalid.Append("custom_fields", err.Error())
+			continue
+		}
+		p.CustomFields[name] = &v
+	}
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
+
+// HostCustomFields are the notes and custom field values of a host.
+type HostCustomFields struct {
+	HostID       uint                  `json:"host_id"`
+	Notes        string                `json:"notes"`
+	CustomFields HostCustomFieldValues `json:"custom_fields"`
+}
+
+// HostCustomFieldsSpec is the spec used to set the notes and custom fields
+// of hosts via fleetctl apply. Hosts maps the identifier of the hosts
+// (hostname, UUID or serial number) to their notes and custom fields.
+type HostCustomFieldsSpec struct {
+	Hosts map[string]*HostCustomFieldsPayload `json:"hosts"`
+}
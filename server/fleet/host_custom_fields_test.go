@@ -0,0 +1,80 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHostCustomFieldDefinitions(t *testing.T) {
+	invalid := &InvalidArgumentError{}
+	ValidateHostCustomFieldDefinitions([]HostCustomFieldDefinition{
+		{Name: "owner", Type: HostCustomFieldTypeString},
+		{Name: "cost_center2", Type: HostCustomFieldTypeNumber},
+		{Name: "purchased_on", Type: HostCustomFieldTypeDate},
+	}, invalid)
+	require.False(t, invalid.HasErrors())
+
+	cases := []struct {
+		def     HostCustomFieldDefinition
+		wantErr string
+	}{
+		{HostCustomFieldDefinition{Type: HostCustomFieldTypeString}, "name is required"},
+		{HostCustomFieldDefinition{Name: "Owner", Type: HostCustomFieldTypeString}, "name must start with a letter"},
+		{HostCustomFieldDefinition{Name: "1owner", Type: HostCustomFieldTypeString}, "name must start with a letter"},
+		{HostCustomFieldDefinition{Name: "asset-tag", Type: HostCustomFieldTypeString}, "name must start with a letter"},
+		{HostCustomFieldDefinition{Name: string(make([]byte, MaxHostCustomFieldNameLength+1)), Type: HostCustomFieldTypeString}, "name cannot be longer than"},
+		{HostCustomFieldDefinition{Name: "owner"}, `invalid type ""`},
+		{HostCustomFieldDefinition{Name: "owner", Type: "bool"}, `invalid type "bool"`},
+	}
+	for _, c := range cases {
+		invalid := &InvalidArgumentError{}
+		ValidateHostCustomFieldDefinitions([]HostCustomFieldDefinition{c.def}, invalid)
+		require.ErrorContains(t, invalid, c.wantErr, c.def)
+	}
+
+	invalid = &InvalidArgumentError{}
+	ValidateHostCustomFieldDefinitions([]HostCustomFieldDefinition{
+		{Name: "owner", Type: HostCustomFieldTypeString},
+		{Name: "owner", Type: HostCustomFieldTypeNumber},
+	}, invalid)
+	require.ErrorContains(t, invalid, `host_custom_fields[1].name duplicate name "owner"`)
+}
+
+func TestHostCustomFieldsPayloadValidate(t *testing.T) {
+	defs := []HostCustomFieldDefinition{
+		{Name: "owner", Type: HostCustomFieldTypeString},
+		{Name: "cost", Type: HostCustomFieldTypeNumber},
+		{Name: "purchased_on", Type: HostCustomFieldTypeDate},
+	}
+
+	payload := HostCustomFieldsPayload{
+		Notes: ptr.String("  some notes \n"),
+		CustomFields: map[string]*string{
+			"owner":        ptr.String(" alice "),
+			"cost":         ptr.String("12.5"),
+			"purchased_on": nil,
+		},
+	}
+	require.NoError(t, payload.Validate(defs))
+	require.Equal(t, "some notes", *payload.Notes)
+	require.Equal(t, "alice", *payload.CustomFields["owner"])
+	require.Equal(t, "12.5", *payload.CustomFields["cost"])
+	require.Nil(t, payload.CustomFields["purchased_on"])
+
+	cases := []struct {
+		payload HostCustomFieldsPayload
+		wantErr string
+	}{
+		{HostCustomFieldsPayload{Notes: ptr.String(string(make([]byte, MaxHostNotesLength+1)))}, "notes cannot be longer than"},
+		{HostCustomFieldsPayload{CustomFields: map[string]*string{"nope": ptr.String("x")}}, `custom field "nope" is not defined`},
+		{HostCustomFieldsPayload{CustomFields: map[string]*string{"nope": nil}}, `custom field "nope" is not defined`},
+		{HostCustomFieldsPayload{CustomFields: map[string]*string{"owner": ptr.String(" ")}}, `the value of "owner" cannot be empty`},
+		{HostCustomFieldsPayload{CustomFields: map[string]*string{"cost": ptr.String("ten")}}, `the value of "cost" must be a number`},
+		{HostCustomFieldsPayload{CustomFields: map[string]*string{"purchased_on": ptr.String("05/14/2024")}}, `the value of "purchased_on" must be a date`},
+	}
+	for _, c := range cases {
+		require.ErrorContains(t, c.payload.Validate(defs), c.wantErr)
+	}
+}
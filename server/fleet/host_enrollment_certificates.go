@@ -0,0 +1,48 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+// HostEnrollmentCertificate is a TLS client certificate that a host used to
+// enroll instead of an enroll secret. The certificate is bound to the first
+// host that enrolled with it and cannot be used to enroll another host.
+type HostEnrollmentCertificate struct {
+	ID     uint `json:"id" db:"id"`
+	HostID uint `json:"host_id" db:"host_id"`
+	// SHA256 is the hex-encoded SHA-256 fingerprint of the DER certificate.
+	SHA256        string     `json:"sha256" db:"sha256"`
+	SerialNumber  string     `json:"serial_number" db:"serial_number"`
+	Subject       string     `json:"subject" db:"subject"`
+	Issuer        string     `json:"issuer" db:"issuer"`
+	NotValidAfter time.Time  `json:"not_valid_after" db:"not_valid_after"`
+	RevokedAt     *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+
+	// HostUUID and HostTeamID are the hardware UUID and team of the bound host,
+	// they are only set when the certificate is loaded by fingerprint.
+	HostUUID   string `json:"-" db:"host_uuid"`
+	HostTeamID *uint  `json:"-" db:"host_team_id"`
+}
+
+// NewHostEnrollmentCertificate returns the enrollment certificate record
+// binding cert to the host.
+func NewHostEnrollmentCertificate(hostID uint, cert *x509.Certificate) *HostEnrollmentCertificate {
+	return &HostEnrollmentCertificate{
+		HostID:        hostID,
+		SHA256:        CertificateSHA256(cert),
+		SerialNumber:  cert.SerialNumber.Text(16),
+		Subject:       cert.Subject.String(),
+		Issuer:        cert.Issuer.String(),
+		NotValidAfter: cert.NotAfter.UTC(),
+	}
+}
+
+// CertificateSHA256 returns the hex-encoded SHA-256 fingerprint of cert.
+func CertificateSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,184 @@
+package fleet
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileRetrievalSettings contains the settings that control which files can
+// be retrieved from hosts. It is configured globally for hosts with no team
+// and per team for the team's hosts. File retrieval is disabled when no
+// allowed path is configured.
+type FileRetrievalSettings struct {
+	// AllowedPaths is the list of absolute paths of the files that can be
+	// retrieved. A path ending with a "/" allows all files in that directory
+	// and its subdirectories, and path.Match patterns (e.g. "/var/log/*.log")
+	// are supported. On Windows hosts, paths are matched case-insensitively
+	// and can use either "/" or "\" as separator.
+	AllowedPaths []string `json:"allowed_paths"`
+	// NotifyEndUser indicates if a notification is displayed to the end user
+	// of the host when a file is retrieved.
+	NotifyEndUser bool `json:"notify_end_user"`
+}
+
+// Validate checks that the allowed paths are absolute and are valid patterns.
+func (s FileRetrievalSettings) Validate() error {
+	for _, p := range s.AllowedPaths {
+		if !isAbsoluteRetrievalPath(p) {
+			return fmt.Errorf("%q is not an absolute path", p)
+		}
+		if _, err := path.Match(normalizeRetrievalPath(p), ""); err != nil {
+			return fmt.Errorf("%q is not a valid pattern: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Allows returns true if the file path can be retrieved from a host of the
+// provided platform (as returned by Host.FleetPlatform).
+func (s FileRetrievalSettings) Allows(filePath, hostPlatform string) bool {
+	if !isAbsoluteRetrievalPath(filePath) {
+		return false
+	}
+	filePath = normalizeRetrievalPath(filePath)
+	for _, seg := range strings.Split(filePath, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+
+	windows := hostPlatform == "windows"
+	if windows {
+		filePath = strings.ToLower(filePath)
+	}
+	for _, allowed := range s.AllowedPaths {
+		allowed = normalizeRetrievalPath(allowed)
+		if windows {
+			allowed = strings.ToLower(allowed)
+		}
+
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(filePath, allowed) && len(filePath) > len(allowed) {
+				return true
+			}
+			continue
+		}
+		if allowed == filePath {
+			return true
+		}
+		if ok, _ := path.Match(allowed, filePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRetrievalPath converts the Windows separators to "/" so that the
+// same matching rules apply to all platforms.
+func normalizeRetrievalPath(p string) string {
+	return strings.ReplaceAll(p, `\`, "/")
+}
+
+// isAbsoluteRetrievalPath returns true if p is an absolute Unix path or an
+// absolute Windows path with a drive letter (e.g. "C:\Windows").
+func isAbsoluteRetrievalPath(p string) bool {
+	p = normalizeRetrievalPath(p)
+	if strings.HasPrefix(p, "/") {
+		return true
+	}
+	if len(p) >= 3 && p[1] == ':' && p[2] == '/' {
+		c := p[0] | 0x20 // lowercase
+		return c >= 'a' && c <= 'z'
+	}
+	return false
+}
+
+// HostFileRetrievalStatus is the status of a file retrieval request.
+type HostFileRetrievalStatus string
+
+const (
+	HostFileRetrievalStatusPending   HostFileRetrievalStatus = "pending"
+	HostFileRetrievalStatusCompleted HostFileRetrievalStatus = "completed"
+	HostFileRetrievalStatusFailed    HostFileRetrievalStatus = "failed"
+)
+
+const (
+	// MaxHostFileRetrievalSize is the maximum size of a file that can be
+	// retrieved from a host.
+	MaxHostFileRetrievalSize = 10 * 1024 * 1024
+	// MaxHostFileRetrievalPendingAge is the age after which a file retrieval
+	// request is not sent to the host anymore if it did not upload the file.
+	MaxHostFileRetrievalPendingAge = 24 * time.Hour
+)
+
+// HostFileRetrievalPayload is the payload used to request the retrieval of a
+// file from a host.
+type HostFileRetrievalPayload struct {
+	HostID uint `json:"-"`
+	// Path is the absolute path of the file on the host.
+	Path string `json:"path"`
+	// NotifyEndUser is filled automatically from the file retrieval settings
+	// that apply to the host.
+	NotifyEndUser bool `json:"-"`
+	// UserID is filled automatically from the context's user (the authenticated
+	// user that made the API request).
+	UserID *uint `json:"-"`
+}
+
+// HostFileRetrieval is a request to retrieve a file from a host, along with
+// the status of the upload of the file.
+type HostFileRetrieval struct {
+	// ID is the unique row identifier of the file retrieval.
+	ID uint `json:"-" db:"id"`
+	// HostID is the host from which the file is retrieved.
+	HostID uint `json:"host_id" db:"host_id"`
+	// ExecutionID is the unique identifier of the file retrieval request.
+	ExecutionID string `json:"execution_id" db:"execution_id"`
+	Path        string `json:"path" db:"path"`
+	// NotifyEndUser indicates if the host must notify its end user that the
+	// file was retrieved.
+	NotifyEndUser bool `json:"notify_end_user" db:"notify_end_user"`
+	// UserID is the id of the user that requested the file retrieval.
+	UserID *uint                   `json:"-" db:"user_id"`
+	Status HostFileRetrievalStatus `json:"status" db:"status"`
+	// Error is the error reported by the host if it failed to read the file.
+	Error string `json:"error,omitempty" db:"error"`
+	// Size is the size in bytes of the file uploaded by the host.
+	Size        int64      `json:"size" db:"size"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+
+	// TeamID is the team of the host, it is used for authorization.
+	TeamID *uint `json:"team_id" db:"-"`
+	// AllowedPaths is only set when the request is sent to the host, with the
+	// allowed paths that currently apply to it. The agent checks the path
+	// again once symbolic links are resolved.
+	AllowedPaths []string `json:"allowed_paths,omitempty" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (r *HostFileRetrieval) AuthzType() string {
+	return "host_file_retrieval"
+}
+
+// Filename returns the name of the file used to download the retrieved file.
+func (r *HostFileRetrieval) Filename() string {
+	name := path.Base(normalizeRetrievalPath(r.Path))
+	if name == "/" || name == "." {
+		name = "file"
+	}
+	return r.ExecutionID + "-" + name
+}
+
+// HostFileRetrievalResultPayload is the payload sent by orbit with the
+// contents of the retrieved file.
+type HostFileRetrievalResultPayload struct {
+	HostID      uint   `json:"host_id"`
+	ExecutionID string `json:"execution_id"`
+	// Error is set if the file could not be read, in which case Data is empty.
+	Error string `json:"error"`
+	// Data is the content of the file.
+	Data []byte `json:"data"`
+}
@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRetrievalSettingsValidate(t *testing.T) {
+	require.NoError(t, FileRetrievalSettings{}.Validate())
+	require.NoError(t, FileRetrievalSettings{AllowedPaths: []string{
+		"/var/log/",
+		"/etc/hosts",
+		"/Users/*/Library/Logs/*.log",
+		`C:\Windows\System32\winevt\Logs\`,
+		"c:/ProgramData/app.log",
+	}}.Validate())
+
+	err := FileRetrievalSettings{AllowedPaths: []string{"/etc/hosts", "var/log/"}}.Validate()
+	require.ErrorContains(t, err, `"var/log/" is not an absolute path`)
+	err = FileRetrievalSettings{AllowedPaths: []string{`C:Windows`}}.Validate()
+	require.ErrorContains(t, err, "is not an absolute path")
+	err = FileRetrievalSettings{AllowedPaths: []string{"/var/log/[a-"}}.Validate()
+	require.ErrorContains(t, err, "is not a valid pattern")
+}
+
+func TestFileRetrievalSettingsAllows(t *testing.T) {
+	settings := FileRetrievalSettings{AllowedPaths: []string{
+		"/var/log/",
+		"/etc/hosts",
+		"/Users/*/Library/Logs/*.log",
+		`C:\Windows\System32\winevt\Logs\`,
+	}}
+
+	cases := []struct {
+		path     string
+		platform string
+		want     bool
+	}{
+		{"/var/log/system.log", "darwin", true},
+		{"/var/log/apt/history.log", "ubuntu", true},
+		{"/var/log/", "darwin", false},
+		{"/var/log", "darwin", false},
+		{"/var/log/../../etc/shadow", "ubuntu", false},
+		{"/etc/hosts", "ubuntu", true},
+		{"/etc/hosts.allow", "ubuntu", false},
+		{"/etc/shadow", "ubuntu", false},
+		{"/Users/anna/Library/Logs/app.log", "darwin", true},
+		{"/Users/anna/Library/Logs/app.txt", "darwin", false},
+		{"/Users/anna/Library/Logs/sub/app.log", "darwin", false},
+		{"var/log/system.log", "darwin", false},
+		{"", "darwin", false},
+		{`C:\Windows\System32\winevt\Logs\System.evtx`, "windows", true},
+		{`c:\windows\system32\winevt\logs\System.evtx`, "windows", true},
+		{`C:/Windows/System32/winevt/Logs/System.evtx`, "windows", true},
+		{`C:\Windows\System32\config\SAM`, "windows", false},
+		{`C:\Windows\System32\winevt\Logs\..\..\config\SAM`, "windows", false},
+		// case-insensitive matching is only for Windows hosts
+		{"/VAR/LOG/system.log", "darwin", false},
+	}
+	for _, c := range cases {
+		t.Run(c.platform+":"+c.path, func(t *testing.T) {
+			require.Equal(t, c.want, settings.Allows(c.path, c.platform))
+		})
+	}
+
+	require.False(t, FileRetrievalSettings{}.Allows("/etc/hosts", "ubuntu"))
+}
+
+func TestHostFileRetrievalFilename(t *testing.T) {
+	require.Equal(t, "abc-system.log", (&HostFileRetrieval{ExecutionID: "abc", Path: "/var/log/system.log"}).Filename())
+	require.Equal(t, "abc-hosts", (&HostFileRetrieval{ExecutionID: "abc", Path: `C:\Windows\System32\drivers\etc\hosts`}).Filename())
+}
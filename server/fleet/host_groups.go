@@ -0,0 +1,105 @@
+package fleet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HostGroup is a named, static collection of hosts. Unlike labels, its
+// membership is not computed from a query, and unlike teams, a host can belong
+// to any number of groups and a group does not carry any setting. It is used
+// to target a set of hosts picked ad hoc (e.g. from search results or a CSV
+// file) with live queries and scripts.
+type HostGroup struct {
+	ID uint `json:"id" db:"id"`
+	// Name is the unique, human friendly name of the group.
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// AuthorID is the id of the user that created the group.
+	AuthorID *uint `json:"author_id" db:"author_id"`
+	// HostCount is the number of hosts in the group that the user can see.
+	HostCount uint      `json:"host_count" db:"host_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (g *HostGroup) AuthzType() string {
+	return "host_group"
+}
+
+// MaxHostGroupNameLength is the maximum length of the name of a host group.
+const MaxHostGroupNameLength = 255
+
+// HostGroupPayload is the payload used to create or modify a host group.
+type HostGroupPayload struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// Validate checks the payload, the name is required when creating a group.
+func (p *HostGroupPayload) Validate(create bool) error {
+	if p.Name == nil {
+		if create {
+			return NewInvalidArgumentError("name", "A name is required.")
+		}
+		return nil
+	}
+
+	name := strings.TrimSpace(*p.Name)
+	if name == "" {
+		return NewInvalidArgumentError("name", "The name cannot be empty.")
+	}
+	if len(name) > MaxHostGroupNameLength {
+		return NewInvalidArgumentError("name", fmt.Sprintf("The name cannot be longer than %d characters.", MaxHostGroupNameLength))
+	}
+	p.Name = &name
+	return nil
+}
+
+// HostGroupHostsPayload selects the hosts to add to or remove from a host
+// group. The selections are combined.
+type HostGroupHostsPayload struct {
+	// HostIDs are the ids of the hosts.
+	HostIDs []uint `json:"host_ids"`
+	// Identifiers are hostnames, UUIDs or serial numbers of the hosts, e.g. the
+	// values of a CSV file.
+	Identifiers []string `json:"identifiers"`
+	// Filters selects the hosts matching the filters of a host search, using
+	// the same format as the filters to transfer hosts to a team.
+	Filters *map[string]interface{} `json:"filters"`
+}
+
+// Empty returns true if no hosts are selected.
+func (p HostGroupHostsPayload) Empty() bool {
+	return len(p.HostIDs) == 0 && len(p.Identifiers) == 0 && p.Filters == nil
+}
+
+// MaxHostGroupScriptRunHosts is the maximum number of hosts in a group that a
+// script can be run on in a single request.
+const MaxHostGroupScriptRunHosts = 5000
+
+// HostGroupScriptRun is the result of running a saved script on the hosts of
+// a host group.
+type HostGroupScriptRun struct {
+	HostGroupID uint `json:"host_group_id"`
+	ScriptID    uint `json:"script_id"`
+	// Executions are the script executions queued on the hosts.
+	Executions []HostGroupScriptExecution `json:"executions"`
+	// Skipped are the hosts on which the script could not be queued.
+	Skipped []HostGroupScriptSkippedHost `json:"skipped"`
+}
+
+// HostGroupScriptExecution is a script execution queued on a host of a group.
+type HostGroupScriptExecution struct {
+	HostID      uint   `json:"host_id"`
+	ExecutionID string `json:"execution_id"`
+}
+
+// HostGroupScriptSkippedHost is a host of a group on which a script could not
+// be queued, along with the reason.
+type HostGroupScriptSkippedHost struct {
+	HostID uint   `json:"host_id"`
+	Error  string `json:"error"`
+}
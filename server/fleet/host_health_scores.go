@@ -0,0 +1,101 @@
+package fleet
+
+import "time"
+
+// HostHealthScore is the health score of a host, from 0 (least healthy) to
+// 100 (healthy). It is recalculated periodically from the host's failing
+// policies, exploitable vulnerabilities, agent health and MDM status, each
+// deducting a capped penalty from 100.
+type HostHealthScore struct {
+	HostID                 uint      `json:"-" db:"host_id"`
+	Score                  int       `json:"score" db:"score"`
+	FailingPoliciesPenalty int       `json:"failing_policies_penalty" db:"failing_policies_penalty"`
+	VulnerabilitiesPenalty int       `json:"vulnerabilities_penalty" db:"vulnerabilities_penalty"`
+	AgentPenalty           int       `json:"agent_penalty" db:"agent_penalty"`
+	MDMPenalty             int       `json:"mdm_penalty" db:"mdm_penalty"`
+	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	// HostHealthScoreBatchSize is the number of hosts whose health score is
+	// recalculated at once by the host health scores cron job.
+	HostHealthScoreBatchSize = 1000
+	// HostHealthScoreStaleCheckIn is the duration after which a host that did
+	// not check in is penalized.
+	HostHealthScoreStaleCheckIn = 24 * time.Hour
+	// HostHealthScoreRecoveryWindow is the window in which the fleetd service
+	// recoveries (crashes and watchdog restarts) of a host are penalized.
+	HostHealthScoreRecoveryWindow = 7 * 24 * time.Hour
+)
+
+// Weights of the signals of the host health score, and the maximum penalty
+// of each category of signals.
+const (
+	healthScoreFailingPolicyWeight         = 5
+	healthScoreFailingCriticalPolicyWeight = 15
+	healthScoreMaxFailingPoliciesPenalty   = 40
+
+	healthScoreExploitableVulnWeight     = 10
+	healthScoreMaxVulnerabilitiesPenalty = 30
+
+	healthScoreStaleCheckInWeight    = 15
+	healthScoreServiceRecoveryWeight = 5
+	healthScoreMaxAgentPenalty       = 20
+
+	healthScoreMDMUnenrolledWeight     = 10
+	healthScoreFailedMDMProfilesWeight = 5
+	healthScoreMaxMDMPenalty           = 10
+)
+
+// HostHealthScoreInputs are the signals of a host used to compute its health
+// score.
+type HostHealthScoreInputs struct {
+	HostID   uint   `db:"host_id"`
+	Platform string `db:"platform"`
+	// FailingPolicies does not include the failing critical policies.
+	FailingPolicies         int `db:"failing_policies"`
+	FailingCriticalPolicies int `db:"failing_critical_policies"`
+	// ExploitableVulnerabilities is the number of CVEs affecting the host's
+	// software or operating system that are known to be exploited (per the
+	// CISA catalog).
+	ExploitableVulnerabilities int       `db:"exploitable_vulnerabilities"`
+	SeenTime                   time.Time `db:"seen_time"`
+	// ServiceRecoveries is the number of fleetd service recoveries in the
+	// HostHealthScoreRecoveryWindow.
+	ServiceRecoveries int `db:"service_recoveries"`
+	// MDMEnrolled is nil if the host never reported its MDM status.
+	MDMEnrolled       *bool `db:"mdm_enrolled"`
+	FailedMDMProfiles int   `db:"failed_mdm_profiles"`
+}
+
+// ComputeHostHealthScore computes the health score of the host from its
+// signals. The MDM status is only taken into account if Fleet's MDM is
+// enabled for the host's platform, as reported by mdmEnabled.
+func ComputeHostHealthScore(in HostHealthScoreInputs, now time.Time, mdmEnabled func(platform string) bool) HostHealthScore {
+	hs := HostHealthScore{HostID: in.HostID}
+
+	hs.FailingPoliciesPenalty = min(healthScoreMaxFailingPoliciesPenalty,
+		in.FailingPolicies*healthScoreFailingPolicyWeight+in.FailingCriticalPolicies*healthScoreFailingCriticalPolicyWeight)
+	hs.VulnerabilitiesPenalty = min(healthScoreMaxVulnerabilitiesPenalty,
+		in.ExploitableVulnerabilities*healthScoreExploitableVulnWeight)
+
+	agent := in.ServiceRecoveries * healthScoreServiceRecoveryWeight
+	if now.Sub(in.SeenTime) > HostHealthScoreStaleCheckIn {
+		agent += healthScoreStaleCheckInWeight
+	}
+	hs.AgentPenalty = min(healthScoreMaxAgentPenalty, agent)
+
+	if mdmEnabled(in.Platform) {
+		var mdm int
+		if in.MDMEnrolled != nil && !*in.MDMEnrolled {
+			mdm += healthScoreMDMUnenrolledWeight
+		}
+		if in.FailedMDMProfiles > 0 {
+			mdm += healthScoreFailedMDMProfilesWeight
+		}
+		hs.MDMPenalty = min(healthScoreMaxMDMPenalty, mdm)
+	}
+
+	hs.Score = 100 - hs.FailingPoliciesPenalty - hs.VulnerabilitiesPenalty - hs.AgentPenalty - hs.MDMPenalty
+	return hs
+}
@@ -0,0 +1,69 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeHostHealthScore(t *testing.T) {
+	now := time.Now()
+	mdmEnabled := func(platform string) bool { return platform == "darwin" }
+
+	cases := []struct {
+		desc string
+		in   HostHealthScoreInputs
+		want HostHealthScore
+	}{
+		{
+			desc: "healthy host",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now, MDMEnrolled: ptr.Bool(true)},
+			want: HostHealthScore{HostID: 1, Score: 100},
+		},
+		{
+			desc: "failing policies",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now, FailingPolicies: 2, FailingCriticalPolicies: 1},
+			want: HostHealthScore{HostID: 1, Score: 75, FailingPoliciesPenalty: 25},
+		},
+		{
+			desc: "failing policies penalty is capped",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now, FailingPolicies: 20, FailingCriticalPolicies: 5},
+			want: HostHealthScore{HostID: 1, Score: 60, FailingPoliciesPenalty: 40},
+		},
+		{
+			desc: "exploitable vulnerabilities",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now, ExploitableVulnerabilities: 2},
+			want: HostHealthScore{HostID: 1, Score: 80, VulnerabilitiesPenalty: 20},
+		},
+		{
+			desc: "stale check-in and service recoveries",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now.Add(-48 * time.Hour), ServiceRecoveries: 3},
+			want: HostHealthScore{HostID: 1, Score: 80, AgentPenalty: 20},
+		},
+		{
+			desc: "unenrolled from MDM with failed profiles",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "darwin", SeenTime: now, MDMEnrolled: ptr.Bool(false), FailedMDMProfiles: 2},
+			want: HostHealthScore{HostID: 1, Score: 90, MDMPenalty: 10},
+		},
+		{
+			desc: "MDM status ignored if MDM is not enabled for the platform",
+			in:   HostHealthScoreInputs{HostID: 1, Platform: "windows", SeenTime: now, MDMEnrolled: ptr.Bool(false), FailedMDMProfiles: 2},
+			want: HostHealthScore{HostID: 1, Score: 100},
+		},
+		{
+			desc: "least healthy host",
+			in: HostHealthScoreInputs{
+				HostID: 1, Platform: "darwin", SeenTime: now.Add(-72 * time.Hour), FailingCriticalPolicies: 3,
+				ExploitableVulnerabilities: 5, ServiceRecoveries: 1, MDMEnrolled: ptr.Bool(false),
+			},
+			want: HostHealthScore{HostID: 1, Score: 0, FailingPoliciesPenalty: 40, VulnerabilitiesPenalty: 30, AgentPenalty: 20, MDMPenalty: 10},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, ComputeHostHealthScore(c.in, now, mdmEnabled))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LockWipeSettings contains the settings that control how hosts are locked
+// and wiped. It is configured globally for hosts with no team and per team
+// for the team's hosts.
+type LockWipeSettings struct {
+	// RequireSecondApprover indicates if a lock or wipe request must be
+	// approved by a second user (by sending the same request) before the
+	// command is sent to the host.
+	RequireSecondApprover bool `json:"require_second_approver"`
+	// NotifyEndUser indicates if the end user of the host is notified by email
+	// when the host is locked, unlocked or wiped.
+	NotifyEndUser bool `json:"notify_end_user"`
+}
+
+const (
+	HostLockWipeActionLock = "lock"
+	HostLockWipeActionWipe = "wipe"
+)
+
+// HostLockWipeApprovalExpiry is the duration after which a lock or wipe
+// request that is waiting for approval expires. A new request must then be
+// made.
+const HostLockWipeApprovalExpiry = 24 * time.Hour
+
+// HostLockWipeApprovalRequest is a lock or wipe request of a host that is
+// waiting to be approved by a second user.
+type HostLockWipeApprovalRequest struct {
+	HostID uint   `db:"host_id"`
+	Action string `db:"action"`
+	// RequestedByUserID is the ID of the user who made the request, it is nil
+	// if that user has been deleted.
+	RequestedByUserID *uint     `db:"requested_by_user_id"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+// IsExpired returns true if the request can't be approved anymore.
+func (r *HostLockWipeApprovalRequest) IsExpired(now time.Time) bool {
+	return now.Sub(r.CreatedAt) > HostLockWipeApprovalExpiry
+}
+
+// LockWipeConfirmationToken returns the token that can be provided instead of
+// the host's serial number to confirm a lock or wipe request, e.g. for hosts
+// that don't report a serial number.
+func (h *Host) LockWipeConfirmationToken() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", h.ID, h.UUID)))
+	return strings.ToUpper(hex.EncodeToString(sum[:4]))
+}
+
+// IsValidLockWipeConfirmation returns true if the confirmation provided with
+// a lock or wipe request matches the host's serial number or its
+// confirmation token. The comparison is case-insensitive.
+func (h *Host) IsValidLockWipeConfirmation(confirmation string) bool {
+	confirmation = strings.TrimSpace(confirmation)
+	if confirmation == "" {
+		return false
+	}
+	if h.HardwareSerial != "" && strings.EqualFold(confirmation, h.HardwareSerial) {
+		return true
+	}
+	return strings.EqualFold(confirmation, h.LockWipeConfirmationToken())
+}
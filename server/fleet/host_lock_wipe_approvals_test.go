@@ -0,0 +1,45 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLockWipeConfirmation(t *testing.T) {
+	withSerial := &Host{ID: 1, UUID: "uuid-1", HardwareSerial: "C02ABC123"}
+	noSerial := &Host{ID: 2, UUID: "uuid-2"}
+
+	// the token is stable and depends on the host
+	require.Len(t, withSerial.LockWipeConfirmationToken(), 8)
+	require.Equal(t, withSerial.LockWipeConfirmationToken(), (&Host{ID: 1, UUID: "uuid-1"}).LockWipeConfirmationToken())
+	require.NotEqual(t, withSerial.LockWipeConfirmationToken(), noSerial.LockWipeConfirmationToken())
+
+	cases := []struct {
+		desc         string
+		host         *Host
+		confirmation string
+		want         bool
+	}{
+		{"empty", withSerial, "", false},
+		{"blank", noSerial, "  ", false},
+		{"serial", withSerial, "C02ABC123", true},
+		{"serial case-insensitive", withSerial, " c02abc123 ", true},
+		{"wrong serial", withSerial, "C02ABC124", false},
+		{"token", withSerial, withSerial.LockWipeConfirmationToken(), true},
+		{"token without serial", noSerial, noSerial.LockWipeConfirmationToken(), true},
+		{"token of another host", noSerial, withSerial.LockWipeConfirmationToken(), false},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, c.host.IsValidLockWipeConfirmation(c.confirmation))
+		})
+	}
+}
+
+func TestHostLockWipeApprovalRequestIsExpired(t *testing.T) {
+	now := time.Now()
+	require.False(t, (&HostLockWipeApprovalRequest{CreatedAt: now.Add(-time.Hour)}).IsExpired(now))
+	require.True(t, (&HostLockWipeApprovalRequest{CreatedAt: now.Add(-HostLockWipeApprovalExpiry - time.Minute)}).IsExpired(now))
+}
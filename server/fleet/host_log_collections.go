@@ -0,0 +1,154 @@
+package fleet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HostLogCollectionType is the type of logs collected from a host.
+type HostLogCollectionType string
+
+const (
+	// HostLogCollectionWindowsEventLog collects channels of the Windows Event
+	// Log (e.g. "System", "Security", "Microsoft-Windows-PowerShell/Operational").
+	HostLogCollectionWindowsEventLog HostLogCollectionType = "windows_event_log"
+	// HostLogCollectionMacOSUnifiedLog collects the entries of the macOS
+	// unified log that match a predicate.
+	HostLogCollectionMacOSUnifiedLog HostLogCollectionType = "macos_unified_log"
+)
+
+// HostLogCollectionStatus is the status of a log collection request.
+type HostLogCollectionStatus string
+
+const (
+	HostLogCollectionStatusPending   HostLogCollectionStatus = "pending"
+	HostLogCollectionStatusCompleted HostLogCollectionStatus = "completed"
+	HostLogCollectionStatusFailed    HostLogCollectionStatus = "failed"
+)
+
+const (
+	// MaxHostLogCollectionSize is the maximum size of the compressed logs
+	// that a host can upload for a log collection request.
+	MaxHostLogCollectionSize = 10 * 1024 * 1024
+	// MaxHostLogCollectionRange is the maximum time range that can be
+	// requested for a log collection.
+	MaxHostLogCollectionRange = 7 * 24 * time.Hour
+	// MaxHostLogCollectionPendingAge is the age after which a log collection
+	// request is not sent to the host anymore if it did not upload the logs.
+	MaxHostLogCollectionPendingAge = 24 * time.Hour
+)
+
+// HostLogCollectionPayload is the payload used to request the collection of
+// logs from a host.
+type HostLogCollectionPayload struct {
+	HostID uint                  `json:"-"`
+	Type   HostLogCollectionType `json:"type"`
+	// Channels is the list of Windows Event Log channels to collect, only
+	// valid for the windows_event_log type.
+	Channels []string `json:"channels"`
+	// Predicate is the filter predicate for the macOS unified log (as accepted
+	// by `log show --predicate`), only valid for the macos_unified_log type.
+	Predicate string    `json:"predicate"`
+	StartTime time.Time `json:"start_time"`
+	// EndTime defaults to the time of the request if not provided.
+	EndTime time.Time `json:"end_time"`
+	// UserID is filled automatically from the context's user (the authenticated
+	// user that made the API request).
+	UserID *uint `json:"-"`
+}
+
+// Validate checks that the payload is valid for a host of the provided
+// platform (as stored in the hosts table).
+func (p HostLogCollectionPayload) Validate(hostPlatform string) error {
+	switch p.Type {
+	case HostLogCollectionWindowsEventLog:
+		if hostPlatform != "windows" {
+			return NewInvalidArgumentError("type", "Windows event logs can only be collected from Windows hosts.")
+		}
+		if len(p.Channels) == 0 {
+			return NewInvalidArgumentError("channels", "At least one channel is required.")
+		}
+		for _, c := range p.Channels {
+			if strings.TrimSpace(c) == "" {
+				return NewInvalidArgumentError("channels", "Channel names cannot be empty.")
+			}
+		}
+		if p.Predicate != "" {
+			return NewInvalidArgumentError("predicate", "A predicate is only supported for macOS unified logs.")
+		}
+	case HostLogCollectionMacOSUnifiedLog:
+		if hostPlatform != "darwin" {
+			return NewInvalidArgumentError("type", "Unified logs can only be collected from macOS hosts.")
+		}
+		if strings.TrimSpace(p.Predicate) == "" {
+			return NewInvalidArgumentError("predicate", "A predicate is required.")
+		}
+		if len(p.Channels) > 0 {
+			return NewInvalidArgumentError("channels", "Channels are only supported for Windows event logs.")
+		}
+	default:
+		return NewInvalidArgumentError("type", fmt.Sprintf("Invalid log type, must be one of %q or %q.",
+			HostLogCollectionWindowsEventLog, HostLogCollectionMacOSUnifiedLog))
+	}
+
+	switch {
+	case p.StartTime.IsZero():
+		return NewInvalidArgumentError("start_time", "A start time is required.")
+	case !p.EndTime.IsZero() && !p.StartTime.Before(p.EndTime):
+		return NewInvalidArgumentError("start_time", "The start time must be before the end time.")
+	case !p.EndTime.IsZero() && p.EndTime.Sub(p.StartTime) > MaxHostLogCollectionRange:
+		return NewInvalidArgumentError("end_time", "The time range cannot be longer than 7 days.")
+	}
+	return nil
+}
+
+// HostLogCollection is a request to collect logs from a host, along with the
+// status of the upload of the collected logs.
+type HostLogCollection struct {
+	// ID is the unique row identifier of the log collection.
+	ID uint `json:"-" db:"id"`
+	// HostID is the host from which the logs are collected.
+	HostID uint `json:"host_id" db:"host_id"`
+	// ExecutionID is the unique identifier of the log collection request.
+	ExecutionID string                `json:"execution_id" db:"execution_id"`
+	Type        HostLogCollectionType `json:"type" db:"type"`
+	Channels    []string              `json:"channels,omitempty" db:"-"`
+	Predicate   string                `json:"predicate,omitempty" db:"predicate"`
+	StartTime   time.Time             `json:"start_time" db:"start_time"`
+	EndTime     time.Time             `json:"end_time" db:"end_time"`
+	// UserID is the id of the user that requested the log collection.
+	UserID *uint                   `json:"-" db:"user_id"`
+	Status HostLogCollectionStatus `json:"status" db:"status"`
+	// Error is the error reported by the host if it failed to collect the logs.
+	Error string `json:"error,omitempty" db:"error"`
+	// Size is the size in bytes of the compressed logs uploaded by the host.
+	Size        int64      `json:"size" db:"size"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+
+	// TeamID is the team of the host, it is used for authorization.
+	TeamID *uint `json:"team_id" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (c *HostLogCollection) AuthzType() string {
+	return "host_log_collection"
+}
+
+// Filename returns the name of the file used to download the collected logs.
+func (c *HostLogCollection) Filename() string {
+	return fmt.Sprintf("%s-%s.tar.gz", c.Type, c.ExecutionID)
+}
+
+// HostLogCollectionResultPayload is the payload sent by orbit with the
+// collected logs.
+type HostLogCollectionResultPayload struct {
+	HostID      uint   `json:"host_id"`
+	ExecutionID string `json:"execution_id"`
+	// Error is set if the logs could not be collected, in which case Data is
+	// empty.
+	Error string `json:"error"`
+	// Data is the gzip-compressed tar archive of the collected logs.
+	Data []byte `json:"data"`
+}
@@ -0,0 +1,101 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLogCollectionPayloadValidate(t *testing.T) {
+	start := time.Date(2024, 4, 22, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	cases := []struct {
+		desc        string
+		payload     HostLogCollectionPayload
+		platform    string
+		errContains string
+	}{
+		{
+			desc:     "valid windows",
+			payload:  HostLogCollectionPayload{Type: HostLogCollectionWindowsEventLog, Channels: []string{"System"}, StartTime: start, EndTime: end},
+			platform: "windows",
+		},
+		{
+			desc:     "valid macos",
+			payload:  HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, Predicate: `process == "sshd"`, StartTime: start, EndTime: end},
+			platform: "darwin",
+		},
+		{
+			desc:        "invalid type",
+			payload:     HostLogCollectionPayload{Type: "syslog", StartTime: start, EndTime: end},
+			platform:    "ubuntu",
+			errContains: "Invalid log type",
+		},
+		{
+			desc:        "windows logs on macos",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionWindowsEventLog, Channels: []string{"System"}, StartTime: start, EndTime: end},
+			platform:    "darwin",
+			errContains: "only be collected from Windows hosts",
+		},
+		{
+			desc:        "unified logs on windows",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, Predicate: "true", StartTime: start, EndTime: end},
+			platform:    "windows",
+			errContains: "only be collected from macOS hosts",
+		},
+		{
+			desc:        "missing channels",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionWindowsEventLog, StartTime: start, EndTime: end},
+			platform:    "windows",
+			errContains: "At least one channel is required",
+		},
+		{
+			desc:        "empty channel",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionWindowsEventLog, Channels: []string{"System", " "}, StartTime: start, EndTime: end},
+			platform:    "windows",
+			errContains: "Channel names cannot be empty",
+		},
+		{
+			desc:        "predicate on windows",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionWindowsEventLog, Channels: []string{"System"}, Predicate: "true", StartTime: start, EndTime: end},
+			platform:    "windows",
+			errContains: "only supported for macOS",
+		},
+		{
+			desc:        "missing predicate",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, StartTime: start, EndTime: end},
+			platform:    "darwin",
+			errContains: "A predicate is required",
+		},
+		{
+			desc:        "missing start time",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, Predicate: "true", EndTime: end},
+			platform:    "darwin",
+			errContains: "A start time is required",
+		},
+		{
+			desc:        "start after end",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, Predicate: "true", StartTime: end, EndTime: start},
+			platform:    "darwin",
+			errContains: "must be before the end time",
+		},
+		{
+			desc:        "range too long",
+			payload:     HostLogCollectionPayload{Type: HostLogCollectionMacOSUnifiedLog, Predicate: "true", StartTime: start, EndTime: start.Add(8 * 24 * time.Hour)},
+			platform:    "darwin",
+			errContains: "cannot be longer than 7 days",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := c.payload.Validate(c.platform)
+			if c.errContains == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, c.errContains)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package fleet
+
+import "time"
+
+// EnrollmentApprovalSettings contains the settings of the enrollment approval
+// mode.
+type EnrollmentApprovalSettings struct {
+	// Enabled makes the hosts that enroll for the first time (via osquery,
+	// fleetd or a manual Apple MDM enrollment) land in a pending approval state.
+	// Until an admin approves them, pending hosts do not receive configuration
+	// profiles, scripts or policies. Hosts that are synced from Apple Business
+	// Manager are trusted and never pending approval.
+	//
+	// Disabling the mode does not approve the hosts that are already pending.
+	Enabled bool `json:"enabled"`
+}
+
+// The list of sources of an enrollment that is pending approval.
+const (
+	HostEnrollmentSourceOsquery = "osquery"
+	HostEnrollmentSourceOrbit   = "orbit"
+	HostEnrollmentSourceMDM     = "mdm"
+)
+
+// HostPendingApproval is a host that enrolled while the enrollment approval
+// mode was enabled and that was not approved yet. It contains the information
+// that an admin needs to verify the host before approving it.
+type HostPendingApproval struct {
+	HostID         uint    `json:"host_id" db:"host_id"`
+	Hostname       string  `json:"hostname" db:"hostname"`
+	ComputerName   string  `json:"computer_name" db:"computer_name"`
+	HardwareSerial string  `json:"hardware_serial" db:"hardware_serial"`
+	HardwareModel  string  `json:"hardware_model" db:"hardware_model"`
+	Platform       string  `json:"platform" db:"platform"`
+	TeamID         *uint   `json:"team_id" db:"team_id"`
+	TeamName       *string `json:"team_name" db:"team_name"`
+	// OwnerEmail is the email of the end user of the host as reported by the
+	// host (e.g. from Google Chrome profiles or the IdP used for MDM
+	// enrollment), nil if it is not known yet.
+	OwnerEmail *string `json:"owner_email" db:"owner_email"`
+	// Source is how the host enrolled, one of the HostEnrollmentSource
+	// constants.
+	Source    string    `json:"source" db:"source"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DisplayName returns the name of the host as displayed in the UI, see
+// Host.DisplayName.
+func (h *HostPendingApproval) DisplayName() string {
+	return HostDisplayName(h.ComputerName, h.Hostname, h.HardwareModel, h.HardwareSerial)
+}
@@ -0,0 +1,132 @@
+package fleet
+
+import (
+	"fmt"
+	"time"
+)
+
+// HostPprofProfileType is the type of Go runtime profile that can be captured
+// from the fleetd agent (orbit) running on a host.
+type HostPprofProfileType string
+
+const (
+	HostPprofProfileCPU       HostPprofProfileType = "cpu"
+	HostPprofProfileHeap      HostPprofProfileType = "heap"
+	HostPprofProfileGoroutine HostPprofProfileType = "goroutine"
+)
+
+// HostPprofCaptureStatus is the status of a pprof capture request.
+type HostPprofCaptureStatus string
+
+const (
+	HostPprofCaptureStatusPending   HostPprofCaptureStatus = "pending"
+	HostPprofCaptureStatusCompleted HostPprofCaptureStatus = "completed"
+	HostPprofCaptureStatusFailed    HostPprofCaptureStatus = "failed"
+)
+
+const (
+	// DefaultHostPprofCPUDuration is the duration of a CPU profile when none
+	// is provided in the request.
+	DefaultHostPprofCPUDuration = 30 * time.Second
+	// MaxHostPprofCPUDuration is the maximum duration of a CPU profile.
+	MaxHostPprofCPUDuration = 2 * time.Minute
+	// MaxHostPprofCaptureSize is the maximum size of a profile uploaded by a
+	// host.
+	MaxHostPprofCaptureSize = 10 * 1024 * 1024
+	// MaxHostPprofCapturePendingAge is the age after which a pprof capture
+	// request is not sent to the host anymore. A profile is only useful to
+	// diagnose the current performance of the agent, so it is shorter than for
+	// other requests.
+	MaxHostPprofCapturePendingAge = time.Hour
+)
+
+// HostPprofCapturePayload is the payload used to request a pprof capture from
+// a host.
+type HostPprofCapturePayload struct {
+	HostID uint                 `json:"-"`
+	Type   HostPprofProfileType `json:"type"`
+	// DurationSeconds is the duration of the capture, for CPU profiles only.
+	// It defaults to DefaultHostPprofCPUDuration.
+	DurationSeconds uint `json:"duration_seconds"`
+	// UserID is filled automatically from the context's user (the authenticated
+	// user that made the API request).
+	UserID *uint `json:"-"`
+}
+
+// Validate checks the payload and sets the default duration of CPU profiles.
+func (p *HostPprofCapturePayload) Validate() error {
+	switch p.Type {
+	case HostPprofProfileCPU:
+		if p.DurationSeconds == 0 {
+			p.DurationSeconds = uint(DefaultHostPprofCPUDuration.Seconds())
+		}
+		if p.DurationSeconds > uint(MaxHostPprofCPUDuration.Seconds()) {
+			return NewInvalidArgumentError("duration_seconds",
+				fmt.Sprintf("The duration cannot be longer than %d seconds.", uint(MaxHostPprofCPUDuration.Seconds())))
+		}
+	case HostPprofProfileHeap, HostPprofProfileGoroutine:
+		if p.DurationSeconds != 0 {
+			return NewInvalidArgumentError("duration_seconds", "A duration is only supported for cpu profiles.")
+		}
+	case "":
+		return NewInvalidArgumentError("type", "A profile type is required.")
+	default:
+		return NewInvalidArgumentError("type", fmt.Sprintf("Invalid profile type, must be one of %q, %q or %q.",
+			HostPprofProfileCPU, HostPprofProfileHeap, HostPprofProfileGoroutine))
+	}
+	return nil
+}
+
+// HostPprofCapture is a request to capture a pprof profile of the fleetd
+// agent running on a host, along with the status of its upload.
+type HostPprofCapture struct {
+	// ID is the unique row identifier of the pprof capture.
+	ID uint `json:"-" db:"id"`
+	// HostID is the host on which the profile is captured.
+	HostID uint `json:"host_id" db:"host_id"`
+	// ExecutionID is the unique identifier of the pprof capture request.
+	ExecutionID     string               `json:"execution_id" db:"execution_id"`
+	Type            HostPprofProfileType `json:"type" db:"type"`
+	DurationSeconds uint                 `json:"duration_seconds" db:"duration_seconds"`
+	// UserID is the id of the user that requested the pprof capture.
+	UserID *uint                  `json:"-" db:"user_id"`
+	Status HostPprofCaptureStatus `json:"status" db:"status"`
+	// Error is the error reported by the host if it failed to capture the
+	// profile.
+	Error string `json:"error,omitempty" db:"error"`
+	// Size is the size in bytes of the profile uploaded by the host.
+	Size        int64      `json:"size" db:"size"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+
+	// TeamID is the team of the host, it is used for authorization.
+	TeamID *uint `json:"team_id" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (c *HostPprofCapture) AuthzType() string {
+	return "host_pprof_capture"
+}
+
+// Duration returns the duration of the capture, zero for profiles that are
+// a snapshot (heap and goroutine).
+func (c *HostPprofCapture) Duration() time.Duration {
+	return time.Duration(c.DurationSeconds) * time.Second
+}
+
+// Filename returns the name of the file used to download the profile.
+func (c *HostPprofCapture) Filename() string {
+	return fmt.Sprintf("%s-%s.pprof", c.ExecutionID, c.Type)
+}
+
+// HostPprofCaptureResultPayload is the payload sent by orbit with the
+// captured profile.
+type HostPprofCaptureResultPayload struct {
+	HostID      uint   `json:"host_id"`
+	ExecutionID string `json:"execution_id"`
+	// Error is set if the profile could not be captured, in which case Data is
+	// empty.
+	Error string `json:"error"`
+	// Data is the profile in the gzip-compressed protobuf format of pprof.
+	Data []byte `json:"data"`
+}
@@ -0,0 +1,62 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPprofCapturePayloadValidate(t *testing.T) {
+	cases := []struct {
+		desc         string
+		payload      HostPprofCapturePayload
+		wantDuration uint
+		errContains  string
+	}{
+		{
+			desc:         "cpu with default duration",
+			payload:      HostPprofCapturePayload{Type: HostPprofProfileCPU},
+			wantDuration: 30,
+		},
+		{
+			desc:         "cpu with duration",
+			payload:      HostPprofCapturePayload{Type: HostPprofProfileCPU, DurationSeconds: 120},
+			wantDuration: 120,
+		},
+		{
+			desc:        "cpu duration too long",
+			payload:     HostPprofCapturePayload{Type: HostPprofProfileCPU, DurationSeconds: 121},
+			errContains: "cannot be longer than 120 seconds",
+		},
+		{
+			desc:    "heap",
+			payload: HostPprofCapturePayload{Type: HostPprofProfileHeap},
+		},
+		{
+			desc:        "goroutine with duration",
+			payload:     HostPprofCapturePayload{Type: HostPprofProfileGoroutine, DurationSeconds: 10},
+			errContains: "only supported for cpu profiles",
+		},
+		{
+			desc:        "missing type",
+			payload:     HostPprofCapturePayload{},
+			errContains: "A profile type is required",
+		},
+		{
+			desc:        "invalid type",
+			payload:     HostPprofCapturePayload{Type: "mutex"},
+			errContains: "Invalid profile type",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := c.payload.Validate()
+			if c.errContains == "" {
+				require.NoError(t, err)
+				require.Equal(t, c.wantDuration, c.payload.DurationSeconds)
+				return
+			}
+			require.ErrorContains(t, err, c.errContains)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package fleet
+
+import (
+	"strings"
+	"time"
+)
+
+// RemoteQuerySettings contains the settings that control if one-off osquery
+// queries can be run on hosts via the fleetd agent. It is configured globally
+// for hosts with no team and per team for the team's hosts.
+type RemoteQuerySettings struct {
+	// Enabled indicates if remote queries can be requested for the hosts.
+	Enabled bool `json:"enabled"`
+}
+
+// HostRemoteQueryStatus is the status of a remote query request.
+type HostRemoteQueryStatus string
+
+const (
+	HostRemoteQueryStatusPending   HostRemoteQueryStatus = "pending"
+	HostRemoteQueryStatusCompleted HostRemoteQueryStatus = "completed"
+	HostRemoteQueryStatusFailed    HostRemoteQueryStatus = "failed"
+)
+
+const (
+	// MaxHostRemoteQueryLength is the maximum length of the SQL statement of a
+	// remote query.
+	MaxHostRemoteQueryLength = 10 * 1024
+	// MaxHostRemoteQueryRows is the maximum number of rows that a host can
+	// return for a remote query.
+	MaxHostRemoteQueryRows = 1000
+	// MaxHostRemoteQueryResultSize is the maximum size of the JSON-encoded rows
+	// returned by a host for a remote query.
+	MaxHostRemoteQueryResultSize = 1024 * 1024
+	// MaxHostRemoteQueryPendingAge is the age after which a remote query
+	// request is not sent to the host anymore. Remote queries are meant to
+	// return the current state of the host, so the results of a stale request
+	// would be misleading.
+	MaxHostRemoteQueryPendingAge = 15 * time.Minute
+)
+
+// HostRemoteQueryPayload is the payload used to request a remote query on a
+// host.
+type HostRemoteQueryPayload struct {
+	HostID uint   `json:"-"`
+	Query  string `json:"query"`
+	// UserID is filled automatically from the context's user (the authenticated
+	// user that made the API request).
+	UserID *uint `json:"-"`
+}
+
+// Validate checks the payload and normalizes the query by removing the
+// surrounding whitespace and the trailing semicolons.
+func (p *HostRemoteQueryPayload) Validate() error {
+	p.Query = strings.TrimRight(strings.TrimSpace(p.Query), "; \t\r\n")
+	switch {
+	case p.Query == "":
+		return NewInvalidArgumentError("query", "A query is required.")
+	case len(p.Query) > MaxHostRemoteQueryLength:
+		return NewInvalidArgumentError("query", "The query is too long.")
+	case strings.Contains(p.Query, ";"):
+		return NewInvalidArgumentError("query", "Only a single SQL statement can be run.")
+	}
+	return nil
+}
+
+// HostRemoteQuery is a request to run a one-off osquery query on a host via
+// the fleetd agent, along with its results.
+type HostRemoteQuery struct {
+	// ID is the unique row identifier of the remote query.
+	ID uint `json:"-" db:"id"`
+	// HostID is the host on which the query is run.
+	HostID uint `json:"host_id" db:"host_id"`
+	// ExecutionID is the unique identifier of the remote query request.
+	ExecutionID string `json:"execution_id" db:"execution_id"`
+	Query       string `json:"query" db:"query"`
+	// UserID is the id of the user that requested the remote query.
+	UserID *uint                 `json:"-" db:"user_id"`
+	Status HostRemoteQueryStatus `json:"status" db:"status"`
+	// Error is the error reported by the host if it failed to run the query.
+	Error string `json:"error,omitempty" db:"error"`
+	// RowCount is the number of rows returned by the host.
+	RowCount    int        `json:"row_count" db:"row_count"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+	// Rows are the rows returned by the host. They are only loaded when a
+	// single remote query is retrieved.
+	Rows []map[string]string `json:"rows,omitempty" db:"-"`
+
+	// TeamID is the team of the host, it is used for authorization.
+	TeamID *uint `json:"team_id" db:"-"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (q *HostRemoteQuery) AuthzType() string {
+	return "host_remote_query"
+}
+
+// HostRemoteQueryResultPayload is the payload sent by orbit with the results
+// of a remote query.
+type HostRemoteQueryResultPayload struct {
+	HostID      uint   `json:"host_id"`
+	ExecutionID string `json:"execution_id"`
+	// Error is set if the query could not be run, in which case Rows is
+	// empty.
+	Error string              `json:"error"`
+	Rows  []map[string]string `json:"rows"`
+}
@@ -0,0 +1,55 @@
+package fleet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRemoteQueryPayloadValidate(t *testing.T) {
+	cases := []struct {
+		desc        string
+		query       string
+		wantQuery   string
+		errContains string
+	}{
+		{
+			desc:      "simple query",
+			query:     "SELECT * FROM osquery_info",
+			wantQuery: "SELECT * FROM osquery_info",
+		},
+		{
+			desc:      "trailing semicolons and whitespace",
+			query:     "  SELECT 1 ; ;\n",
+			wantQuery: "SELECT 1",
+		},
+		{
+			desc:        "empty query",
+			query:       " ; ",
+			errContains: "A query is required",
+		},
+		{
+			desc:        "multiple statements",
+			query:       "SELECT 1; SELECT 2",
+			errContains: "Only a single SQL statement",
+		},
+		{
+			desc:        "query too long",
+			query:       "SELECT '" + strings.Repeat("a", MaxHostRemoteQueryLength) + "'",
+			errContains: "The query is too long",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			payload := HostRemoteQueryPayload{Query: c.query}
+			err := payload.Validate()
+			if c.errContains == "" {
+				require.NoError(t, err)
+				require.Equal(t, c.wantQuery, payload.Query)
+				return
+			}
+			require.ErrorContains(t, err, c.errContains)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package fleet
+
+import "time"
+
+// HostServiceRecoveryReason is the reason why the fleetd service had to be
+// recovered on a host.
+type HostServiceRecoveryReason string
+
+const (
+	// HostServiceRecoveryCrash is reported when fleetd stopped without a clean
+	// shutdown and was restarted by the service control manager.
+	HostServiceRecoveryCrash HostServiceRecoveryReason = "crash"
+	// HostServiceRecoveryWatchdog is reported when fleetd was stopped by its
+	// watchdog because its main loop stopped sending heartbeats, and was
+	// restarted by the service control manager.
+	HostServiceRecoveryWatchdog HostServiceRecoveryReason = "watchdog"
+)
+
+// IsValid returns true if r is a known service recovery reason.
+func (r HostServiceRecoveryReason) IsValid() bool {
+	switch r {
+	case HostServiceRecoveryCrash, HostServiceRecoveryWatchdog:
+		return true
+	default:
+		return false
+	}
+}
+
+// MaxHostServiceRecoveryDetailsLength is the maximum length of the details of
+// a service recovery, longer details are truncated.
+const MaxHostServiceRecoveryDetailsLength = 1024
+
+// HostServiceRecoveryPayload is the payload sent by fleetd to report that the
+// recovery actions of its service fired.
+type HostServiceRecoveryPayload struct {
+	Reason HostServiceRecoveryReason `json:"reason"`
+	// Details is a human-readable description of the recovery, e.g. the time
+	// of the last heartbeat before the watchdog fired.
+	Details string `json:"details"`
+	// RecoveredAt is the time at which fleetd started again after the
+	// failure.
+	RecoveredAt time.Time `json:"recovered_at"`
+}
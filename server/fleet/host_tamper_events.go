@@ -0,0 +1,44 @@
+package fleet
+
+import "time"
+
+// HostTamperEventType is the type of a tamper event detected by fleetd on a
+// host.
+type HostTamperEventType string
+
+const (
+	// HostTamperEventOsquerydKilled is reported when osqueryd exits
+	// unexpectedly multiple times within a short period.
+	HostTamperEventOsquerydKilled HostTamperEventType = "osqueryd_killed"
+	// HostTamperEventServiceDisabled is reported when the fleetd launch daemon
+	// (macOS), service (Windows) or systemd unit (Linux) is disabled.
+	HostTamperEventServiceDisabled HostTamperEventType = "service_disabled"
+	// HostTamperEventConfigModified is reported when one of fleetd's
+	// configuration files is modified outside of fleetd.
+	HostTamperEventConfigModified HostTamperEventType = "config_modified"
+)
+
+// IsValid returns true if t is a known tamper event type.
+func (t HostTamperEventType) IsValid() bool {
+	switch t {
+	case HostTamperEventOsquerydKilled, HostTamperEventServiceDisabled, HostTamperEventConfigModified:
+		return true
+	default:
+		return false
+	}
+}
+
+// MaxHostTamperEventDetailsLength is the maximum length of the details of a
+// tamper event, longer details are truncated.
+const MaxHostTamperEventDetailsLength = 1024
+
+// HostTamperEventPayload is the payload sent by fleetd to report a tamper
+// event.
+type HostTamperEventPayload struct {
+	Type HostTamperEventType `json:"type"`
+	// Details is a human-readable description of the event, e.g. the path of
+	// the modified file.
+	Details string `json:"details"`
+	// DetectedAt is the time at which fleetd detected the event.
+	DetectedAt time.Time `json:"detected_at"`
+}
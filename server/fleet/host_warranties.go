@@ -0,0 +1,116 @@
+package fleet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WarrantyVendor is a hardware vendor whose warranty API can be queried to
+// enrich the hosts with their purchase and warranty end dates.
+type WarrantyVendor string
+
+const (
+	WarrantyVendorApple  WarrantyVendor = "apple"
+	WarrantyVendorDell   WarrantyVendor = "dell"
+	WarrantyVendorLenovo WarrantyVendor = "lenovo"
+)
+
+// IsValid returns true if v is a supported warranty vendor.
+func (v WarrantyVendor) IsValid() bool {
+	switch v {
+	case WarrantyVendorApple, WarrantyVendorDell, WarrantyVendorLenovo:
+		return true
+	default:
+		return false
+	}
+}
+
+// WarrantyVendorForHardwareVendor returns the warranty vendor matching the
+// hardware vendor reported by a host (e.g. "Apple Inc.", "Dell Inc." or
+// "LENOVO"), or false if the vendor is not supported.
+func WarrantyVendorForHardwareVendor(hardwareVendor string) (WarrantyVendor, bool) {
+	hardwareVendor = strings.ToLower(strings.TrimSpace(hardwareVendor))
+	for _, v := range []WarrantyVendor{WarrantyVendorApple, WarrantyVendorDell, WarrantyVendorLenovo} {
+		if strings.HasPrefix(hardwareVendor, string(v)) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+const (
+	// HostWarrantyRefreshInterval is the interval after which the warranty of
+	// a host is looked up again from the vendor's API.
+	HostWarrantyRefreshInterval = 30 * 24 * time.Hour
+	// HostWarrantyLookupBatchSize is the maximum number of hosts looked up in
+	// a single run of the host warranties cron job.
+	HostWarrantyLookupBatchSize = 500
+)
+
+// WarrantyIntegration configures the credentials to query the warranty API of
+// a hardware vendor.
+type WarrantyIntegration struct {
+	Vendor WarrantyVendor `json:"vendor"`
+	// URL overrides the default URL of the vendor's API, e.g. to go through a
+	// proxy.
+	URL string `json:"url"`
+	// ClientID is the client ID for Dell, and the sold-to account number for
+	// Apple GSX. It is not used for Lenovo.
+	ClientID string `json:"client_id"`
+	// APIKey is the client secret for Dell, the client ID token for Lenovo and
+	// the authentication token for Apple GSX.
+	APIKey string `json:"api_key"`
+}
+
+// ValidateWarrantyIntegrations validates the warranty integrations and records
+// any error in invalid. If an API key is masked, it is replaced by the stored
+// one of the same vendor.
+func ValidateWarrantyIntegrations(oldIntgs, newIntgs []*WarrantyIntegration, invalid *InvalidArgumentError) {
+	oldByVendor := make(map[WarrantyVendor]*WarrantyIntegration, len(oldIntgs))
+	for _, intg := range oldIntgs {
+		oldByVendor[intg.Vendor] = intg
+	}
+
+	seen := make(map[WarrantyVendor]bool, len(newIntgs))
+	for i, intg := range newIntgs {
+		key := fmt.Sprintf("integrations.warranty[%d]", i)
+		intg.Vendor = WarrantyVendor(strings.ToLower(strings.TrimSpace(string(intg.Vendor))))
+		if !intg.Vendor.IsValid() {
+			invalid.Append(key+".vendor", fmt.Sprintf("invalid vendor %q, must be one of %q, %q or %q", intg.Vendor,
+				WarrantyVendorApple, WarrantyVendorDell, WarrantyVendorLenovo))
+			continue
+		}
+		if seen[intg.Vendor] {
+			invalid.Append(key+".vendor", fmt.Sprintf("duplicate vendor %q", intg.Vendor))
+			continue
+		}
+		seen[intg.Vendor] = true
+
+		intg.ClientID = strings.TrimSpace(intg.ClientID)
+		intg.URL = strings.TrimSpace(intg.URL)
+		if intg.APIKey == MaskedPassword {
+			if old := oldByVendor[intg.Vendor]; old != nil {
+				intg.APIKey = old.APIKey
+			} else {
+				intg.APIKey = ""
+			}
+		}
+		if strings.TrimSpace(intg.APIKey) == "" {
+			invalid.Append(key+".api_key", "api_key is required")
+		}
+		if intg.ClientID == "" && (intg.Vendor == WarrantyVendorApple || intg.Vendor == WarrantyVendorDell) {
+			invalid.Append(key+".client_id", fmt.Sprintf("client_id is required for vendor %q", intg.Vendor))
+		}
+	}
+}
+
+// HostWarranty is the warranty information of a host as reported by the
+// vendor's API.
+type HostWarranty struct {
+	HostID          uint           `json:"host_id" db:"host_id"`
+	Vendor          WarrantyVendor `json:"vendor" db:"vendor"`
+	PurchaseDate    *time.Time     `json:"purchase_date" db:"purchase_date"`
+	WarrantyEndDate *time.Time     `json:"warranty_end_date" db:"warranty_end_date"`
+	CheckedAt       time.Time      `json:"checked_at" db:"checked_at"`
+}
@@ -0,0 +1,60 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarrantyVendorForHardwareVendor(t *testing.T) {
+	cases := []struct {
+		hardwareVendor string
+		want           WarrantyVendor
+		wantOK         bool
+	}{
+		{"Apple Inc.", WarrantyVendorApple, true},
+		{"Dell Inc.", WarrantyVendorDell, true},
+		{"LENOVO", WarrantyVendorLenovo, true},
+		{" lenovo ", WarrantyVendorLenovo, true},
+		{"HP", "", false},
+		{"VMware, Inc.", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		got, ok := WarrantyVendorForHardwareVendor(c.hardwareVendor)
+		require.Equal(t, c.wantOK, ok, c.hardwareVendor)
+		require.Equal(t, c.want, got, c.hardwareVendor)
+	}
+}
+
+func TestValidateWarrantyIntegrations(t *testing.T) {
+	old := []*WarrantyIntegration{
+		{Vendor: WarrantyVendorDell, ClientID: "id", APIKey: "secret"},
+	}
+
+	intgs := []*WarrantyIntegration{
+		{Vendor: " Dell ", ClientID: " id ", APIKey: MaskedPassword},
+		{Vendor: WarrantyVendorLenovo, APIKey: "token", URL: " https://example.com "},
+	}
+	invalid := &InvalidArgumentError{}
+	ValidateWarrantyIntegrations(old, intgs, invalid)
+	require.False(t, invalid.HasErrors())
+	require.Equal(t, &WarrantyIntegration{Vendor: WarrantyVendorDell, ClientID: "id", APIKey: "secret"}, intgs[0])
+	require.Equal(t, &WarrantyIntegration{Vendor: WarrantyVendorLenovo, APIKey: "token", URL: "https://example.com"}, intgs[1])
+
+	cases := []struct {
+		intgs   []*WarrantyIntegration
+		wantErr string
+	}{
+		{[]*WarrantyIntegration{{Vendor: "hp", APIKey: "k"}}, `invalid vendor "hp"`},
+		{[]*WarrantyIntegration{{Vendor: WarrantyVendorLenovo, APIKey: "k"}, {Vendor: WarrantyVendorLenovo, APIKey: "k"}}, `duplicate vendor "lenovo"`},
+		{[]*WarrantyIntegration{{Vendor: WarrantyVendorLenovo}}, "api_key is required"},
+		{[]*WarrantyIntegration{{Vendor: WarrantyVendorApple, ClientID: "soldto", APIKey: MaskedPassword}}, "api_key is required"},
+		{[]*WarrantyIntegration{{Vendor: WarrantyVendorApple, APIKey: "k"}}, `client_id is required for vendor "apple"`},
+	}
+	for _, c := range cases {
+		invalid := &InvalidArgumentError{}
+		ValidateWarrantyIntegrations(old, c.intgs, invalid)
+		require.ErrorContains(t, invalid, c.wantErr)
+	}
+}
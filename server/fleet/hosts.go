@@ -107,6 +107,28 @@ func (s MDMBootstrapPackageStatus) IsValid() bool {
 	}
 }
 
+// MDMFleetdInstallStatus defines the possible statuses of the installation of
+// fleetd on a host via the MDM InstallEnterpriseApplication command sent after
+// the host enrolled in Apple MDM.
+type MDMFleetdInstallStatus string
+
+const (
+	// MDMFleetdInstallInstalled means fleetd has been installed on the host. It
+	// corresponds to InstallEnterpriseApplicationResponse.Status "Acknowledged".
+	MDMFleetdInstallInstalled = MDMFleetdInstallStatus("installed")
+	// MDMFleetdInstallFailed means fleetd failed to install on the host. It
+	// corresponds to InstallEnterpriseApplicationResponse.Status "Error" or
+	// "CommandFormatError".
+	MDMFleetdInstallFailed = MDMFleetdInstallStatus("failed")
+	// MDMFleetdInstallPending means no response has been received yet for the
+	// command to install fleetd.
+	MDMFleetdInstallPending = MDMFleetdInstallStatus("pending")
+)
+
+// MaxFleetdInstallRetries is the maximum number of times the command to
+// install fleetd is re-sent to a host after a transient failure.
+const MaxFleetdInstallRetries = 3
+
 // NOTE: any changes to the hosts filters is likely to impact at least the following
 // endpoints, due to how they share the same implementation at the Datastore level:
 //
@@ -181,6 +203,12 @@ type HostListOptions struct {
 	MDMEnrollmentStatusFilter MDMEnrollStatus
 	// MunkiIssueIDFilter filters the hosts by munki issue ID.
 	MunkiIssueIDFilter *uint
+	// HostGroupIDFilter filters the hosts by membership in a host group.
+	HostGroupIDFilter *uint
+	// CustomFieldNameFilter and CustomFieldValueFilter filter the hosts by the
+	// value of a custom field, they must be set together.
+	CustomFieldNameFilter  *string
+	CustomFieldValueFilter *string
 
 	// LowDiskSpaceFilter filters the hosts by low disk space (defined as a host
 	// with less than N gigs of disk space available). Note that this is a Fleet
@@ -188,12 +216,30 @@ type HostListOptions struct {
 	// disable it).
 	LowDiskSpaceFilter *int
 
+	// MaxHealthScoreFilter filters the hosts whose health score is at most N.
+	// Hosts whose health score was never computed are excluded.
+	MaxHealthScoreFilter *int
+
+	// CloudProviderFilter, CloudRegionFilter and CloudAccountIDFilter filter
+	// the hosts by the cloud instance they run on, see HostCloudInstance.
+	CloudProviderFilter  *CloudProvider
+	CloudRegionFilter    *string
+	CloudAccountIDFilter *string
+
 	// PopulateSoftware adds the `Software` field to all Hosts returned.
 	PopulateSoftware bool
 
 	// PopulatePolicies adds the `Policies` array field to all Hosts returned.
 	PopulatePolicies bool
 
+	// PopulateCustomFields adds the `Notes` and `CustomFields` fields to all
+	// Hosts returned.
+	PopulateCustomFields bool
+
+	// PopulateWarranty adds the `PurchaseDate` and `WarrantyEndDate` fields to
+	// all Hosts returned.
+	PopulateWarranty bool
+
 	// VulnerabilityFilter filters the hosts by the presence of a vulnerability (CVE)
 	VulnerabilityFilter *string
 }
@@ -221,7 +267,14 @@ func (h HostListOptions) Empty() bool {
 		h.MDMNameFilter == nil &&
 		h.MDMEnrollmentStatusFilter == "" &&
 		h.MunkiIssueIDFilter == nil &&
+		h.HostGroupIDFilter == nil &&
+		h.CustomFieldNameFilter == nil &&
+		h.CustomFieldValueFilter == nil &&
 		h.LowDiskSpaceFilter == nil &&
+		h.MaxHealthScoreFilter == nil &&
+		h.CloudProviderFilter == nil &&
+		h.CloudRegionFilter == nil &&
+		h.CloudAccountIDFilter == nil &&
 		h.OSSettingsFilter == "" &&
 		h.OSSettingsDiskEncryptionFilter == ""
 }
@@ -253,17 +306,24 @@ type Host struct {
 	Hostname         string    `json:"hostname" db:"hostname" csv:"hostname"` // there is a fulltext index on this field
 	UUID             string    `json:"uuid" db:"uuid" csv:"uuid"`             // there is a fulltext index on this field
 	// Platform is the host's platform as defined by osquery's os_version.platform.
-	Platform       string        `json:"platform" csv:"platform"`
-	OsqueryVersion string        `json:"osquery_version" db:"osquery_version" csv:"osquery_version"`
-	OrbitVersion   *string       `json:"orbit_version" db:"orbit_version" csv:"orbit_version"`
-	DesktopVersion *string       `json:"fleet_desktop_version" db:"fleet_desktop_version" csv:"fleet_desktop_version"`
-	ScriptsEnabled *bool         `json:"scripts_enabled" db:"scripts_enabled" csv:"scripts_enabled"`
-	OSVersion      string        `json:"os_version" db:"os_version" csv:"os_version"`
-	Build          string        `json:"build" csv:"build"`
-	PlatformLike   string        `json:"platform_like" db:"platform_like" csv:"platform_like"`
-	CodeName       string        `json:"code_name" db:"code_name" csv:"code_name"`
-	Uptime         time.Duration `json:"uptime" csv:"uptime"`
-	Memory         int64         `json:"memory" sql:"type:bigint" db:"memory" csv:"memory"`
+	Platform       string  `json:"platform" csv:"platform"`
+	OsqueryVersion string  `json:"osquery_version" db:"osquery_version" csv:"osquery_version"`
+	OrbitVersion   *string `json:"orbit_version" db:"orbit_version" csv:"orbit_version"`
+	DesktopVersion *string `json:"fleet_desktop_version" db:"fleet_desktop_version" csv:"fleet_desktop_version"`
+	ScriptsEnabled *bool   `json:"scripts_enabled" db:"scripts_enabled" csv:"scripts_enabled"`
+	// Timezone is the IANA name of the local timezone of the host, as reported
+	// by fleetd. It is nil if it has not been reported.
+	Timezone *string `json:"timezone" db:"timezone" csv:"timezone"`
+	// PendingApproval is true if the host enrolled while the enrollment
+	// approval mode was enabled and was not approved yet, see
+	// EnrollmentApprovalSettings.
+	PendingApproval bool          `json:"pending_approval" db:"pending_approval" csv:"-"`
+	OSVersion       string        `json:"os_version" db:"os_version" csv:"os_version"`
+	Build           string        `json:"build" csv:"build"`
+	PlatformLike    string        `json:"platform_like" db:"platform_like" csv:"platform_like"`
+	CodeName        string        `json:"code_name" db:"code_name" csv:"code_name"`
+	Uptime          time.Duration `json:"uptime" csv:"uptime"`
+	Memory          int64         `json:"memory" sql:"type:bigint" db:"memory" csv:"memory"`
 	// system_info fields
 	CPUType          string `json:"cpu_type" db:"cpu_type" csv:"cpu_type"`
 	CPUSubtype       string `json:"cpu_subtype" db:"cpu_subtype" csv:"cpu_subtype"`
@@ -315,6 +375,11 @@ type Host struct {
 
 	HostIssues `json:"issues,omitempty" csv:"-"`
 
+	// HostChannelSeenTimes is the last time the host was seen by each of the
+	// non-osquery channels (MDM, orbit and Fleet Desktop). Note that it is
+	// only filled in by the main host-returning datastore methods.
+	HostChannelSeenTimes `json:"last_seen_by_channel" csv:"-"`
+
 	// DeviceMapping is in fact included in the CSV export, but it is not directly
 	// encoded from this column, it is processed before marshaling, hence why the
 	// struct tag here has csv:"-".
@@ -357,6 +422,34 @@ type Host struct {
 
 	// Policies is the list of policies and whether it passes for the host
 	Policies *[]*HostPolicy `json:"policies,omitempty" csv:"-"`
+
+	// Notes are the free-form notes of the host. As for CustomFields, it is
+	// only filled in when loading a single host and when listing hosts with
+	// HostListOptions.PopulateCustomFields.
+	Notes *string `json:"notes,omitempty" db:"notes" csv:"notes"`
+	// CustomFields are the values of the custom fields of the host. They are
+	// in fact included in the CSV export, one column per field, but are
+	// processed before marshaling, hence why the struct tag here has csv:"-".
+	CustomFields HostCustomFieldValues `json:"custom_fields,omitempty" db:"custom_fields" csv:"-"`
+
+	// PurchaseDate and WarrantyEndDate are the dates reported by the warranty
+	// API of the host's vendor. They are only filled in when loading a single
+	// host and when listing hosts with HostListOptions.PopulateWarranty.
+	PurchaseDate    *time.Time `json:"purchase_date,omitempty" db:"purchase_date" csv:"purchase_date"`
+	WarrantyEndDate *time.Time `json:"warranty_end_date,omitempty" db:"warranty_end_date" csv:"warranty_end_date"`
+
+	// HealthScore is the health score of the host, from 0 to 100, see
+	// HostHealthScore. It is nil if the score was not computed yet.
+	HealthScore *int `json:"health_score,omitempty" db:"health_score" csv:"health_score"`
+
+	// CloudProvider, CloudInstanceID, CloudRegion, CloudAccountID and
+	// CloudTags are the cloud instance the host runs on, see
+	// HostCloudInstance. They are nil if the host is not a cloud instance.
+	CloudProvider   *CloudProvider    `json:"cloud_provider,omitempty" db:"cloud_provider" csv:"cloud_provider"`
+	CloudInstanceID *string           `json:"cloud_instance_id,omitempty" db:"cloud_instance_id" csv:"cloud_instance_id"`
+	CloudRegion     *string           `json:"cloud_region,omitempty" db:"cloud_region" csv:"cloud_region"`
+	CloudAccountID  *string           `json:"cloud_account_id,omitempty" db:"cloud_account_id" csv:"cloud_account_id"`
+	CloudTags       CloudInstanceTags `json:"cloud_tags,omitempty" db:"cloud_tags" csv:"cloud_tags"`
 }
 
 // HostOrbitInfo maps to the host_orbit_info table in the database, which maps to the orbit_info agent table.
@@ -364,6 +457,7 @@ type HostOrbitInfo struct {
 	Version        string  `json:"version" db:"version"`
 	DesktopVersion *string `json:"desktop_version" db:"desktop_version"`
 	ScriptsEnabled *bool   `json:"scripts_enabled" db:"scripts_enabled"`
+	Timezone       *string `json:"timezone" db:"timezone"`
 }
 
 // HostHealth contains a subset of Host data that indicates how healthy a Host is. For fields with
@@ -454,12 +548,37 @@ type MDMHostData struct {
 	// It is not filled in by all host-returning datastore methods.
 	MacOSSetup *HostMDMMacOSSetup `json:"macos_setup,omitempty" db:"-" csv:"-"`
 
+	// FleetdInstall indicates the status of the installation of fleetd via
+	// MDM on macOS hosts, if fleetd was installed that way.
+	//
+	// It is not filled in by all host-returning datastore methods.
+	FleetdInstall *HostMDMFleetdInstall `json:"fleetd_install,omitempty" db:"-" csv:"-"`
+
+	// MacOSSecurityInfo is the security information reported by the macOS
+	// host in response to the SecurityInfo MDM command.
+	//
+	// It is not filled in by all host-returning datastore methods.
+	MacOSSecurityInfo *HostMDMAppleSecurityInfo `json:"macos_security_info,omitempty" db:"-" csv:"-"`
+
 	// The DeviceStatus and PendingAction fields are not stored in the database
 	// directly, they are read from the GetHostLockWipeStatus datastore method
 	// and determined from those results. They are not filled by all
 	// host-returning methods.
 	DeviceStatus  *string `json:"device_status,omitempty" db:"-" csv:"-"`
 	PendingAction *string `json:"pending_action,omitempty" db:"-" csv:"-"`
+
+	// The IsolationStatus and PendingIsolationAction fields are determined the
+	// same way as DeviceStatus and PendingAction, independently of them as a
+	// host can be isolated from the network while being locked.
+	IsolationStatus        *string `json:"isolation_status,omitempty" db:"-" csv:"-"`
+	PendingIsolationAction *string `json:"pending_isolation_action,omitempty" db:"-" csv:"-"`
+
+	// OsqueryConfigWithheld is true if the scheduled queries and policies are
+	// withheld from the host because its team requires the macOS and Windows
+	// hosts to be enrolled in Fleet MDM and the host is not (see
+	// Features.RequireMDMEnrollment). It is only filled when getting a single
+	// host.
+	OsqueryConfigWithheld *bool `json:"osquery_config_withheld,omitempty" db:"-" csv:"-"`
 }
 
 type HostMDMOSSettings struct {
@@ -524,6 +643,34 @@ type HostMDMMacOSSetup struct {
 	BootstrapPackageName   string                    `db:"bootstrap_package_name" json:"bootstrap_package_name" csv:"-"`
 }
 
+// HostMDMFleetdInstall is the status of the installation of fleetd on a host
+// enrolled in Apple MDM.
+type HostMDMFleetdInstall struct {
+	Status MDMFleetdInstallStatus `db:"status" json:"status" csv:"-"`
+	// Detail is the reason of the failure if Status is "failed".
+	Detail string `db:"-" json:"detail" csv:"-"`
+	// Retries is the number of times the command was re-sent after a failure.
+	Retries     uint   `db:"retries" json:"retries" csv:"-"`
+	CommandUUID string `db:"command_uuid" json:"-" csv:"-"`
+	Result      []byte `db:"result" json:"-" csv:"-"`
+}
+
+// HostMDMAppleSecurityInfo is the security information of a macOS host, as
+// reported by the host in response to the SecurityInfo MDM command. The fields
+// are nil if the host did not report them, e.g. the secure boot level is not
+// reported by the Intel Macs without a T2 chip.
+type HostMDMAppleSecurityInfo struct {
+	FileVaultEnabled         *bool `json:"filevault_enabled" db:"fde_enabled" csv:"-"`
+	FirewallEnabled          *bool `json:"firewall_enabled" db:"firewall_enabled" csv:"-"`
+	FirewallBlockAllIncoming *bool `json:"firewall_block_all_incoming" db:"firewall_block_all_incoming" csv:"-"`
+	FirewallStealthMode      *bool `json:"firewall_stealth_mode" db:"firewall_stealth_mode" csv:"-"`
+	SIPEnabled               *bool `json:"system_integrity_protection_enabled" db:"sip_enabled" csv:"-"`
+	// SecureBootLevel is "full", "medium", "off" or "not supported".
+	SecureBootLevel      *string   `json:"secure_boot_level" db:"secure_boot_level" csv:"-"`
+	RemoteDesktopEnabled *bool     `json:"remote_desktop_enabled" db:"remote_desktop_enabled" csv:"-"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at" csv:"-"`
+}
+
 // PopulateOSSettingsAndMacOSSettings populates the OSSettings and MacOSSettings
 // on the MDMHostData struct. It determines the disk encryption status for the
 // host based on the file-vault profile in its list of profiles and whether its
@@ -727,6 +874,34 @@ func (h *Host) IsEligibleForBitLockerEncryption() bool {
 		(needsEncryption || encryptedWithoutKey)
 }
 
+// IsEligibleForLinuxDiskEncryptionEscrow checks if the Linux host needs to
+// escrow the passphrase of its encrypted disk, either because Fleet has no key
+// for it yet or because a rotation was requested.
+//
+// Note: the *Host structs needs disk encryption data filled in to perform the
+// check.
+func (h *Host) IsEligibleForLinuxDiskEncryptionEscrow() bool {
+	isEncrypted := h.DiskEncryptionEnabled != nil && *h.DiskEncryptionEnabled
+	resetRequested := h.DiskEncryptionResetRequested != nil && *h.DiskEncryptionResetRequested
+
+	return h.FleetPlatform() == "linux" &&
+		h.IsOsqueryEnrolled() &&
+		isEncrypted &&
+		(!h.MDM.EncryptionKeyAvailable || resetRequested)
+}
+
+// IsEligibleForLinuxDiskEncryptionEnforcement checks if the Linux host
+// reported that its root volume is not encrypted, so that the user must be
+// guided to encrypt it.
+//
+// Note: the *Host structs needs disk encryption data filled in to perform the
+// check.
+func (h *Host) IsEligibleForLinuxDiskEncryptionEnforcement() bool {
+	return h.FleetPlatform() == "linux" &&
+		h.IsOsqueryEnrolled() &&
+		h.DiskEncryptionEnabled != nil && !*h.DiskEncryptionEnabled
+}
+
 // HostDisplayName returns ComputerName if it isn't empty. Otherwise, it returns Hostname if it isn't
 // empty. If Hostname is empty and both HardwareSerial and HardwareModel are not empty, it returns a
 // composite string with HardwareModel and HardwareSerial. If all else fails, it returns an empty
@@ -753,6 +928,76 @@ type HostIssues struct {
 	FailingPoliciesCount int `json:"failing_policies_count" db:"failing_policies_count" csv:"-"`
 }
 
+// HostSeenChannel identifies a channel through which a host communicates
+// with Fleet, other than osquery (which is tracked by Host.SeenTime).
+type HostSeenChannel string
+
+const (
+	// HostSeenChannelMDM is used when the host checks in with the MDM server
+	// (e.g. an Apple device reporting command results or an Idle status).
+	HostSeenChannelMDM HostSeenChannel = "mdm"
+	// HostSeenChannelOrbit is used when orbit fetches its configuration.
+	HostSeenChannelOrbit HostSeenChannel = "orbit"
+	// HostSeenChannelFleetDesktop is used when Fleet Desktop pings the server.
+	HostSeenChannelFleetDesktop HostSeenChannel = "fleet_desktop"
+)
+
+// OnlineInterval returns the duration after a host was last seen by the
+// channel during which it is considered online. It includes the
+// OnlineIntervalBuffer.
+func (c HostSeenChannel) OnlineInterval() time.Duration {
+	var interval time.Duration
+	switch c {
+	case HostSeenChannelMDM:
+		// MDM check-ins are driven by push notifications and are much less
+		// frequent than the agents' check-ins.
+		interval = time.Hour
+	case HostSeenChannelOrbit:
+		// orbit fetches its config every 30 seconds
+		interval = 30 * time.Second
+	case HostSeenChannelFleetDesktop:
+		// Fleet Desktop refreshes its summary every 5 minutes
+		interval = 5 * time.Minute
+	}
+	return interval + OnlineIntervalBuffer*time.Second
+}
+
+// HostSeenChannels is the list of all supported HostSeenChannel values.
+var HostSeenChannels = []HostSeenChannel{HostSeenChannelMDM, HostSeenChannelOrbit, HostSeenChannelFleetDesktop}
+
+// HostChannelSeenTimes holds the last time a host was seen by each
+// HostSeenChannel. A nil value means the host was never seen by that channel.
+type HostChannelSeenTimes struct {
+	MDM          *time.Time `json:"mdm" db:"mdm_seen_time" csv:"-"`
+	Orbit        *time.Time `json:"orbit" db:"orbit_seen_time" csv:"-"`
+	FleetDesktop *time.Time `json:"fleet_desktop" db:"desktop_seen_time" csv:"-"`
+}
+
+// SeenTime returns the last time the host was seen by the channel, or nil if
+// it never was.
+func (s HostChannelSeenTimes) SeenTime(c HostSeenChannel) *time.Time {
+	switch c {
+	case HostSeenChannelMDM:
+		return s.MDM
+	case HostSeenChannelOrbit:
+		return s.Orbit
+	case HostSeenChannelFleetDesktop:
+		return s.FleetDesktop
+	}
+	return nil
+}
+
+// IsOnline returns true if any of the channels has seen the host within its
+// online interval.
+func (s HostChannelSeenTimes) IsOnline(now time.Time) bool {
+	for _, c := range HostSeenChannels {
+		if ts := s.SeenTime(c); ts != nil && !ts.Add(c.OnlineInterval()).Before(now) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h Host) AuthzType() string {
 	return "host"
 }
@@ -806,6 +1051,12 @@ func (h *Host) Status(now time.Time) HostStatus {
 	// GenerateHostStatusStatistics and CountHostsInTargets
 	// NOTE: As of Fleet 4.15 StatusMIA is deprecated and will be removed in Fleet 5.0
 
+	// a host seen recently by any channel (MDM, orbit, Fleet Desktop) is
+	// online, even if osquery did not check in (e.g. MDM-only iOS devices).
+	if h.HostChannelSeenTimes.IsOnline(now) {
+		return StatusOnline
+	}
+
 	onlineInterval := h.ConfigTLSRefresh
 	if h.DistributedInterval < h.ConfigTLSRefresh {
 		onlineInterval = h.DistributedInterval
@@ -860,6 +1111,26 @@ func IsUnixLike(hostPlatform string) bool {
 	return false
 }
 
+// IsApplePlatform returns true if the host platform is one of the Apple
+// platforms managed via MDM (macOS, iOS and iPadOS).
+func IsApplePlatform(hostPlatform string) bool {
+	return hostPlatform == "darwin" || hostPlatform == "ios" || hostPlatform == "ipados"
+}
+
+// ApplePlatformFromModel returns the host platform of an Apple device given
+// the model or model name it reports via MDM, e.g. "iPhone14,2" or "iPad".
+// It defaults to "darwin".
+func ApplePlatformFromModel(model string) string {
+	switch {
+	case strings.HasPrefix(model, "iPhone"), strings.HasPrefix(model, "iPod"):
+		return "ios"
+	case strings.HasPrefix(model, "iPad"):
+		return "ipados"
+	default:
+		return "darwin"
+	}
+}
+
 // PlatformFromHost converts the given host platform into
 // the generic platforms known by osquery
 // https://osquery.readthedocs.io/en/stable/deployment/configuration/
@@ -1235,6 +1506,7 @@ type EnrollHostLimiter interface {
 
 type HostMDMCheckinInfo struct {
 	HardwareSerial        string `json:"hardware_serial" db:"hardware_serial"`
+	Platform              string `json:"platform" db:"platform"`
 	InstalledFromDEP      bool   `json:"installed_from_dep" db:"installed_from_dep"`
 	DisplayName           string `json:"display_name" db:"display_name"`
 	TeamID                uint   `json:"team_id" db:"team_id"`
@@ -1249,6 +1521,9 @@ type HostDiskEncryptionKey struct {
 	Decryptable     *bool     `json:"-" db:"decryptable"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 	DecryptedValue  string    `json:"key" db:"-"`
+	// KeySlot is the LUKS key slot of the escrowed passphrase, only set for
+	// Linux hosts.
+	KeySlot *uint `json:"key_slot,omitempty" db:"key_slot"`
 }
 
 // HostSoftwareInstalledPath represents where in the file system a software on a host was installed
@@ -52,6 +52,43 @@ func TestHostStatus(t *testing.T) {
 	}
 }
 
+func TestHostStatusWithChannels(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	now := mockClock.Now()
+	ago := func(d time.Duration) *time.Time {
+		ts := now.Add(-d)
+		return &ts
+	}
+
+	testCases := []struct {
+		name     string
+		channels HostChannelSeenTimes
+		status   HostStatus
+	}{
+		{"never seen", HostChannelSeenTimes{}, StatusOffline},
+		{"mdm recent", HostChannelSeenTimes{MDM: ago(30 * time.Minute)}, StatusOnline},
+		{"mdm old", HostChannelSeenTimes{MDM: ago(2 * time.Hour)}, StatusOffline},
+		{"orbit recent", HostChannelSeenTimes{Orbit: ago(80 * time.Second)}, StatusOnline},
+		{"orbit old", HostChannelSeenTimes{Orbit: ago(2 * time.Minute)}, StatusOffline},
+		{"desktop recent", HostChannelSeenTimes{FleetDesktop: ago(5 * time.Minute)}, StatusOnline},
+		{"desktop old", HostChannelSeenTimes{FleetDesktop: ago(7 * time.Minute)}, StatusOffline},
+		{"one recent channel", HostChannelSeenTimes{MDM: ago(2 * time.Hour), Orbit: ago(10 * time.Second)}, StatusOnline},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			// osquery was last seen a long time ago
+			h := Host{
+				DistributedInterval:  10,
+				ConfigTLSRefresh:     10,
+				SeenTime:             now.Add(-24 * time.Hour),
+				HostChannelSeenTimes: tt.channels,
+			}
+			assert.Equal(t, tt.status, h.Status(now))
+		})
+	}
+}
+
 func TestHostStatusIsValid(t *testing.T) {
 	for _, tt := range []struct {
 		name     string
@@ -144,6 +181,27 @@ func TestPlatformFromHost(t *testing.T) {
 	}
 }
 
+func TestApplePlatformFromModel(t *testing.T) {
+	for _, tc := range []struct {
+		model       string
+		expPlatform string
+	}{
+		{model: "iPhone", expPlatform: "ios"},
+		{model: "iPhone14,2", expPlatform: "ios"},
+		{model: "iPod touch", expPlatform: "ios"},
+		{model: "iPad", expPlatform: "ipados"},
+		{model: "iPad13,18", expPlatform: "ipados"},
+		{model: "MacBookPro18,3", expPlatform: "darwin"},
+		{model: "", expPlatform: "darwin"},
+	} {
+		platform := ApplePlatformFromModel(tc.model)
+		require.Equal(t, tc.expPlatform, platform, tc.model)
+		require.True(t, IsApplePlatform(platform))
+	}
+	require.False(t, IsApplePlatform("windows"))
+	require.False(t, IsApplePlatform(""))
+}
+
 func TestHostDisplayName(t *testing.T) {
 	const (
 		computerName   = "K0mpu73rN4M3"
@@ -312,6 +370,40 @@ func TestIsEligibleForBitLockerEncryption(t *testing.T) {
 	require.True(t, hostThatNeedsEnforcement.IsEligibleForBitLockerEncryption())
 }
 
+func TestIsEligibleForLinuxDiskEncryptionEscrow(t *testing.T) {
+	require.False(t, (&Host{}).IsEligibleForLinuxDiskEncryptionEscrow())
+
+	host := Host{
+		Platform:              "ubuntu",
+		OsqueryHostID:         ptr.String("test"),
+		DiskEncryptionEnabled: ptr.Bool(true),
+	}
+	require.True(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+
+	// only Linux hosts are eligible
+	host.Platform = "windows"
+	require.False(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+	host.Platform = "rhel"
+	require.True(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+
+	// the disk must already be encrypted
+	host.DiskEncryptionEnabled = ptr.Bool(false)
+	require.False(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+	host.DiskEncryptionEnabled = nil
+	require.False(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+	host.DiskEncryptionEnabled = ptr.Bool(true)
+
+	// once the key is escrowed, only a rotation request makes it eligible
+	host.MDM.EncryptionKeyAvailable = true
+	require.False(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+	host.DiskEncryptionResetRequested = ptr.Bool(true)
+	require.True(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+
+	// hosts not enrolled in osquery are not eligible
+	host.OsqueryHostID = nil
+	require.False(t, host.IsEligibleForLinuxDiskEncryptionEscrow())
+}
+
 func TestIsEligibleForDEPMigration(t *testing.T) {
 	testCases := []struct {
 		name                    string
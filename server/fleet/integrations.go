@@ -356,6 +356,7 @@ type Integrations struct {
 	Jira           []*JiraIntegration           `json:"jira"`
 	Zendesk        []*ZendeskIntegration        `json:"zendesk"`
 	GoogleCalendar []*GoogleCalendarIntegration `json:"google_calendar"`
+	Warranty       []*WarrantyIntegration       `json:"warranty"`
 }
 
 // ValidateEnabledHostStatusIntegrations checks that the host status integrations
@@ -376,6 +377,32 @@ func ValidateEnabledHostStatusIntegrations(webhook HostStatusWebhookSettings, in
 	}
 }
 
+func ValidateEnabledCertificateExpiryIntegrations(webhook CertificateExpiryWebhookSettings, invalid *InvalidArgumentError) {
+	if webhook.Enable {
+		if webhook.DestinationURL == "" {
+			invalid.Append("destination_url", "destination_url is required to enable the certificate expiry webhook")
+		}
+		if webhook.DaysBeforeExpiry <= 0 {
+			invalid.Append("days_before_expiry", "days_before_expiry must be > 0 to enable the certificate expiry webhook")
+		}
+		if webhook.HostsThreshold < 0 {
+			invalid.Append("hosts_threshold", "hosts_threshold must be >= 0 to enable the certificate expiry webhook")
+		}
+	}
+}
+
+func ValidateEnabledTamperEventsIntegrations(webhook TamperEventsWebhookSettings, invalid *InvalidArgumentError) {
+	if webhook.Enable && webhook.DestinationURL == "" {
+		invalid.Append("destination_url", "destination_url is required to enable the tamper events webhook")
+	}
+}
+
+func ValidateEnabledMDMEnrollmentIntegrations(webhook MDMEnrollmentWebhookSettings, invalid *InvalidArgumentError) {
+	if webhook.Enable && webhook.DestinationURL == "" {
+		invalid.Append("destination_url", "destination_url is required to enable the MDM enrollment webhook")
+	}
+}
+
 func ValidateGoogleCalendarIntegrations(intgs []*GoogleCalendarIntegration, invalid *InvalidArgumentError) {
 	if len(intgs) > 1 {
 		invalid.Append("integrations.google_calendar", "integrating with >1 Google Workspace service account is not yet supported.")
@@ -25,7 +25,10 @@ type Invite struct {
 	Token      string      `json:"-"`
 	SSOEnabled bool        `json:"sso_enabled" db:"sso_enabled"`
 	GlobalRole null.String `json:"global_role" db:"global_role"`
-	Teams      []UserTeam  `json:"teams"`
+	// TenantID is the tenant of the user that sent the invite, the invited
+	// user joins it.
+	TenantID *uint      `json:"tenant_id,omitempty" db:"tenant_id"`
+	Teams    []UserTeam `json:"teams"`
 }
 
 func (i Invite) AuthzType() string {
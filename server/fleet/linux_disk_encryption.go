@@ -0,0 +1,36 @@
+package fleet
+
+// LinuxDiskEncryptionEnforcementStatus is the state of the enforcement of disk
+// encryption on a Linux host whose root volume is not encrypted.
+type LinuxDiskEncryptionEnforcementStatus string
+
+const (
+	// LinuxDiskEncryptionEnforcementActionRequired means the user was guided
+	// to encrypt the root volume of the host, which requires their action.
+	LinuxDiskEncryptionEnforcementActionRequired LinuxDiskEncryptionEnforcementStatus = "action_required"
+	// LinuxDiskEncryptionEnforcementFailed means the host could not guide the
+	// user, e.g. because no graphical session is available.
+	LinuxDiskEncryptionEnforcementFailed LinuxDiskEncryptionEnforcementStatus = "failed"
+)
+
+// IsValid returns true if the status is a known enforcement status.
+func (s LinuxDiskEncryptionEnforcementStatus) IsValid() bool {
+	switch s {
+	case LinuxDiskEncryptionEnforcementActionRequired, LinuxDiskEncryptionEnforcementFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// MDMLinuxDiskEncryptionSummary reports the number of Linux hosts in each
+// disk encryption status, it has the same shape as the summaries of the other
+// platforms.
+type MDMLinuxDiskEncryptionSummary struct {
+	Verified            uint `json:"verified" db:"verified"`
+	Verifying           uint `json:"verifying" db:"verifying"`
+	ActionRequired      uint `json:"action_required" db:"action_required"`
+	Enforcing           uint `json:"enforcing" db:"enforcing"`
+	Failed              uint `json:"failed" db:"failed"`
+	RemovingEnforcement uint `json:"removing_enforcement" db:"removing_enforcement"`
+}
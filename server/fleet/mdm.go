@@ -166,6 +166,11 @@ type CommandEnqueueResult struct {
 	CommandUUID string `json:"command_uuid,omitempty"`
 	// RequestType is the name of the command.
 	RequestType string `json:"request_type,omitempty"`
+	// EnqueuedUUIDs is the list of host UUIDs the command was enqueued for.
+	EnqueuedUUIDs []string `json:"enqueued_uuids,omitempty"`
+	// SkippedUUIDs is the list of host UUIDs the command was not enqueued for
+	// because they have no MDM enrollment.
+	SkippedUUIDs []string `json:"skipped_uuids,omitempty"`
 	// FailedUUIDs is the list of host UUIDs that failed to receive the command.
 	FailedUUIDs []string `json:"failed_uuids,omitempty"`
 	// Platform is the platform of the hosts targeted by the command.
@@ -237,17 +242,56 @@ type MDMCommand struct {
 }
 
 // MDMCommandListOptions defines the options to control the list of MDM
-// Commands to return. Although it only supports the standard list
-// options for now, in the future we expect to add filtering options.
+// Commands to return.
 //
 // https://github.com/fleetdm/fleet/issues/11008#issuecomment-1503466119
 type MDMCommandListOptions struct {
 	ListOptions
+
+	// Status filters the commands by their platform-agnostic status.
+	Status MDMCommandStatusFilter
+	// RequestType filters the commands by request type (the command name for
+	// Apple, the target LocURI for Windows).
+	RequestType string
+	// TeamID filters the commands by the team of the targeted hosts, 0 for
+	// hosts in no team.
+	TeamID *uint
+	// UpdatedAfter and UpdatedBefore filter the commands by the last update
+	// timestamp of their result (or their creation timestamp if pending).
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+// MDMCommandStatusFilter is the platform-agnostic status used to filter the
+// list of MDM commands. Apple and Windows report results differently (e.g.
+// "Acknowledged" vs a "200" status code), the filter covers both.
+type MDMCommandStatusFilter string
+
+const (
+	// MDMCommandStatusFilterPending matches the commands without a result yet,
+	// or that the Apple device could not process yet ("NotNow").
+	MDMCommandStatusFilterPending MDMCommandStatusFilter = "pending"
+	// MDMCommandStatusFilterAcknowledged matches the commands successfully
+	// processed by the device.
+	MDMCommandStatusFilterAcknowledged MDMCommandStatusFilter = "acknowledged"
+	// MDMCommandStatusFilterError matches the commands that failed on the
+	// device.
+	MDMCommandStatusFilterError MDMCommandStatusFilter = "error"
+)
+
+func (s MDMCommandStatusFilter) IsValid() bool {
+	switch s {
+	case MDMCommandStatusFilterPending, MDMCommandStatusFilterAcknowledged, MDMCommandStatusFilterError:
+		return true
+	default:
+		return false
+	}
 }
 
 type MDMPlatformsCounts struct {
 	MacOS   uint `db:"macos" json:"macos"`
 	Windows uint `db:"windows" json:"windows"`
+	Linux   uint `db:"linux" json:"linux"`
 }
 
 type MDMDiskEncryptionSummary struct {
@@ -373,14 +417,114 @@ type MDMConfigProfilePayload struct {
 	CreatedAt   time.Time                   `json:"created_at" db:"created_at"`
 	UploadedAt  time.Time                   `json:"updated_at" db:"uploaded_at"` // NOTE: JSON field is still `updated_at` for historical reasons, would be an API breaking change
 	Labels      []ConfigurationProfileLabel `json:"labels,omitempty" db:"-"`
+	// LabelsIncludeAny and LabelsExcludeAny are only set for Apple and
+	// Windows configuration profiles.
+	LabelsIncludeAny []ConfigurationProfileLabel `json:"labels_include_any,omitempty" db:"-"`
+	LabelsExcludeAny []ConfigurationProfileLabel `json:"labels_exclude_any,omitempty" db:"-"`
+	// SharedWithAllTeams is true if the profile is a "no team" Apple or
+	// Windows profile that a global admin shared with all teams.
+	SharedWithAllTeams bool `json:"shared_with_all_teams" db:"shared_with_all_teams"`
+	// OptedOut is only set for the shared profiles listed for a team, it is
+	// true if the team opted out of the profile.
+	OptedOut *bool `json:"opted_out,omitempty" db:"opted_out"`
+}
+
+// AddLabel adds the label to the set of labels of the profile it belongs to.
+func (p *MDMConfigProfilePayload) AddLabel(label ConfigurationProfileLabel) {
+	switch {
+	case label.Exclude:
+		p.LabelsExcludeAny = append(p.LabelsExcludeAny, label)
+	case label.RequireAll:
+		p.Labels = append(p.Labels, label)
+	default:
+		p.LabelsIncludeAny = append(p.LabelsIncludeAny, label)
+	}
 }
 
 // MDMProfileBatchPayload represents the payload to batch-set the profiles for
 // a team or no-team.
 type MDMProfileBatchPayload struct {
-	Name     string   `json:"name,omitempty"`
-	Contents []byte   `json:"contents,omitempty"`
-	Labels   []string `json:"labels,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Contents []byte `json:"contents,omitempty"`
+	// Labels are the labels that the hosts must all be members of to receive
+	// the profile.
+	Labels []string `json:"labels,omitempty"`
+	// LabelsIncludeAny and LabelsExcludeAny are only supported by Apple
+	// .mobileconfig and Windows profiles.
+	LabelsIncludeAny []string `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []string `json:"labels_exclude_any,omitempty"`
+	// Rollout are the staged rollout settings of the profile, only supported
+	// by Apple .mobileconfig and Windows profiles.
+	Rollout *MDMProfileRolloutSettings `json:"rollout,omitempty"`
+}
+
+// LabelTargeting returns the label targeting of the profile.
+func (p MDMProfileBatchPayload) LabelTargeting() MDMProfileLabelTargeting {
+	return MDMProfileLabelTargeting{Labels: p.Labels, LabelsIncludeAny: p.LabelsIncludeAny, LabelsExcludeAny: p.LabelsExcludeAny}
+}
+
+// MDMProfileLabelTargeting are the labels that determine the hosts targeted
+// by a configuration profile. A host is targeted if it is a member of all
+// the Labels, of at least one of the LabelsIncludeAny (if any) and of none of
+// the LabelsExcludeAny. A profile without labels targets all the hosts of its
+// team.
+type MDMProfileLabelTargeting struct {
+	Labels           []string `json:"labels,omitempty"`
+	LabelsIncludeAny []string `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []string `json:"labels_exclude_any,omitempty"`
+}
+
+// IsEmpty returns true if no label is set.
+func (t MDMProfileLabelTargeting) IsEmpty() bool {
+	return len(t.Labels)+len(t.LabelsIncludeAny)+len(t.LabelsExcludeAny) == 0
+}
+
+// Names returns the names of all the labels.
+func (t MDMProfileLabelTargeting) Names() []string {
+	names := make([]string, 0, len(t.Labels)+len(t.LabelsIncludeAny)+len(t.LabelsExcludeAny))
+	names = append(names, t.Labels...)
+	names = append(names, t.LabelsIncludeAny...)
+	return append(names, t.LabelsExcludeAny...)
+}
+
+// Validate checks that a label is not used in more than one set of labels.
+func (t MDMProfileLabelTargeting) Validate() error {
+	sets := make(map[string]string)
+	for _, set := range []struct {
+		key    string
+		labels []string
+	}{
+		{"labels", t.Labels},
+		{"labels_include_any", t.LabelsIncludeAny},
+		{"labels_exclude_any", t.LabelsExcludeAny},
+	} {
+		for _, name := range set.labels {
+			if other, ok := sets[name]; ok && other != set.key {
+				return NewInvalidArgumentError(set.key, fmt.Sprintf("label %q cannot be in both %s and %s", name, other, set.key))
+			}
+			sets[name] = set.key
+		}
+	}
+	return nil
+}
+
+// MaxMDMProfileTargetsPreviewHosts is the maximum number of hosts listed in a
+// preview of the hosts targeted by a profile.
+const MaxMDMProfileTargetsPreviewHosts = 20
+
+// MDMProfileTargetsPreview is the set of hosts that a configuration profile
+// with some label targeting would be delivered to.
+type MDMProfileTargetsPreview struct {
+	// HostsCount is the number of hosts targeted by the profile.
+	HostsCount uint `json:"hosts_count" db:"hosts_count"`
+	// Hosts are the first targeted hosts, sorted by id.
+	Hosts []MDMProfileTargetHost `json:"hosts"`
+}
+
+// MDMProfileTargetHost is a host of a MDMProfileTargetsPreview.
+type MDMProfileTargetHost struct {
+	ID          uint   `json:"id" db:"id"`
+	DisplayName string `json:"display_name" db:"display_name"`
 }
 
 func NewMDMConfigProfilePayloadFromWindows(cp *MDMWindowsConfigProfile) *MDMConfigProfilePayload {
@@ -389,13 +533,15 @@ func NewMDMConfigProfilePayloadFromWindows(cp *MDMWindowsConfigProfile) *MDMConf
 		tid = cp.TeamID
 	}
 	return &MDMConfigProfilePayload{
-		ProfileUUID: cp.ProfileUUID,
-		TeamID:      tid,
-		Name:        cp.Name,
-		Platform:    "windows",
-		CreatedAt:   cp.CreatedAt,
-		UploadedAt:  cp.UploadedAt,
-		Labels:      cp.Labels,
+		ProfileUUID:      cp.ProfileUUID,
+		TeamID:           tid,
+		Name:             cp.Name,
+		Platform:         "windows",
+		CreatedAt:        cp.CreatedAt,
+		UploadedAt:       cp.UploadedAt,
+		Labels:           cp.Labels,
+		LabelsIncludeAny: cp.LabelsIncludeAny,
+		LabelsExcludeAny: cp.LabelsExcludeAny,
 	}
 }
 
@@ -405,15 +551,17 @@ func NewMDMConfigProfilePayloadFromApple(cp *MDMAppleConfigProfile) *MDMConfigPr
 		tid = cp.TeamID
 	}
 	return &MDMConfigProfilePayload{
-		ProfileUUID: cp.ProfileUUID,
-		TeamID:      tid,
-		Name:        cp.Name,
-		Identifier:  cp.Identifier,
-		Platform:    "darwin",
-		Checksum:    cp.Checksum,
-		CreatedAt:   cp.CreatedAt,
-		UploadedAt:  cp.UploadedAt,
-		Labels:      cp.Labels,
+		ProfileUUID:      cp.ProfileUUID,
+		TeamID:           tid,
+		Name:             cp.Name,
+		Identifier:       cp.Identifier,
+		Platform:         "darwin",
+		Checksum:         cp.Checksum,
+		CreatedAt:        cp.CreatedAt,
+		UploadedAt:       cp.UploadedAt,
+		Labels:           cp.Labels,
+		LabelsIncludeAny: cp.LabelsIncludeAny,
+		LabelsExcludeAny: cp.LabelsExcludeAny,
 	}
 }
 
@@ -438,8 +586,11 @@ func NewMDMConfigProfilePayloadFromAppleDDM(decl *MDMAppleDeclaration) *MDMConfi
 // MDMProfileSpec represents the spec used to define configuration
 // profiles via yaml files.
 type MDMProfileSpec struct {
-	Path   string   `json:"path,omitempty"`
-	Labels []string `json:"labels,omitempty"`
+	Path             string                     `json:"path,omitempty"`
+	Labels           []string                   `json:"labels,omitempty"`
+	LabelsIncludeAny []string                   `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []string                   `json:"labels_exclude_any,omitempty"`
+	Rollout          *MDMProfileRolloutSettings `json:"rollout,omitempty"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface to add backwards
@@ -487,14 +638,34 @@ func (p *MDMProfileSpec) Copy() *MDMProfileSpec {
 		clone.Labels = make([]string, len(p.Labels))
 		copy(clone.Labels, p.Labels)
 	}
+	if len(p.LabelsIncludeAny) > 0 {
+		clone.LabelsIncludeAny = make([]string, len(p.LabelsIncludeAny))
+		copy(clone.LabelsIncludeAny, p.LabelsIncludeAny)
+	}
+	if len(p.LabelsExcludeAny) > 0 {
+		clone.LabelsExcludeAny = make([]string, len(p.LabelsExcludeAny))
+		copy(clone.LabelsExcludeAny, p.LabelsExcludeAny)
+	}
+	if p.Rollout != nil {
+		rollout := *p.Rollout
+		clone.Rollout = &rollout
+	}
 
 	return &clone
 }
 
-func labelCountMap(labels []string) map[string]int {
+// labelCountMap counts the labels of the spec, keyed by the set of labels
+// they belong to and their name.
+func labelCountMap(spec MDMProfileSpec) map[string]int {
 	counts := make(map[string]int)
-	for _, label := range labels {
-		counts[label]++
+	for _, label := range spec.Labels {
+		counts["labels:"+label]++
+	}
+	for _, label := range spec.LabelsIncludeAny {
+		counts["labels_include_any:"+label]++
+	}
+	for _, label := range spec.LabelsExcludeAny {
+		counts["labels_exclude_any:"+label]++
 	}
 	return counts
 }
@@ -507,8 +678,10 @@ func MDMProfileSpecsMatch(a, b []MDMProfileSpec) bool {
 	}
 
 	pathLabelCounts := make(map[string]map[string]int)
+	pathRollouts := make(map[string]*MDMProfileRolloutSettings)
 	for _, v := range a {
-		pathLabelCounts[v.Path] = labelCountMap(v.Labels)
+		pathLabelCounts[v.Path] = labelCountMap(v)
+		pathRollouts[v.Path] = v.Rollout
 	}
 
 	for _, v := range b {
@@ -517,7 +690,12 @@ func MDMProfileSpecsMatch(a, b []MDMProfileSpec) bool {
 			return false
 		}
 
-		bLabelCounts := labelCountMap(v.Labels)
+		if rollout := pathRollouts[v.Path]; (rollout == nil) != (v.Rollout == nil) ||
+			(rollout != nil && *rollout != *v.Rollout) {
+			return false
+		}
+
+		bLabelCounts := labelCountMap(v)
 		for label, count := range bLabelCounts {
 			if labels[label] != count {
 				return false
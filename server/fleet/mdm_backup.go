@@ -0,0 +1,61 @@
+package fleet
+
+import "time"
+
+// MDMAssetsBundleVersion is the version of the format of the MDM assets
+// bundles exported by this version of Fleet.
+const MDMAssetsBundleVersion = 1
+
+// MDMAssetsBundle is a backup of the MDM assets of a Fleet server, used for
+// disaster recovery or to promote the assets of a staging server to
+// production. It is always exported encrypted with a passphrase.
+type MDMAssetsBundle struct {
+	Version           int                         `json:"version"`
+	ExportedAt        time.Time                   `json:"exported_at"`
+	Assets            []MDMBundleAsset            `json:"assets"`
+	BootstrapPackages []MDMBundleBootstrapPackage `json:"bootstrap_packages"`
+}
+
+// MDMBundleAsset is an MDM certificate, key or token of an MDM assets bundle.
+type MDMBundleAsset struct {
+	Name TenantMDMAssetName `json:"name"`
+	// Tenant is the name of the tenant of the asset, empty for the assets set
+	// in the server configuration (e.g. the APNs certificate).
+	Tenant string `json:"tenant,omitempty"`
+	Value  []byte `json:"value"`
+}
+
+// MDMBundleBootstrapPackage is a bootstrap package of an MDM assets bundle.
+type MDMBundleBootstrapPackage struct {
+	// Team is the name of the team of the package, empty for "No team". Teams
+	// are identified by name as their ids differ between servers.
+	Team   string `json:"team,omitempty"`
+	Name   string `json:"name"`
+	Sha256 []byte `json:"sha256"`
+	Bytes  []byte `json:"bytes"`
+}
+
+// MDMBundleItemKind is the kind of an item of an MDM assets bundle.
+type MDMBundleItemKind string
+
+const (
+	MDMBundleItemAsset            MDMBundleItemKind = "asset"
+	MDMBundleItemBootstrapPackage MDMBundleItemKind = "bootstrap_package"
+)
+
+// MDMAssetsImportItem is an item of an MDM assets bundle that was restored or
+// skipped by an import.
+type MDMAssetsImportItem struct {
+	Kind   MDMBundleItemKind `json:"kind"`
+	Name   string            `json:"name"`
+	Tenant string            `json:"tenant,omitempty"`
+	Team   string            `json:"team,omitempty"`
+	// Reason is the reason why the item was skipped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// MDMAssetsImportResult is the result of the import of an MDM assets bundle.
+type MDMAssetsImportResult struct {
+	Restored []MDMAssetsImportItem `json:"restored"`
+	Skipped  []MDMAssetsImportItem `json:"skipped"`
+}
@@ -0,0 +1,123 @@
+package fleet
+
+import (
+	"math"
+	"time"
+)
+
+// MDMProfileRedeliveryCampaignStatus is the status of a profile redelivery
+// campaign.
+type MDMProfileRedeliveryCampaignStatus string
+
+const (
+	// MDMProfileRedeliveryCampaignRunning is the status of a campaign that
+	// redelivers the profile to its hosts at its configured rate.
+	MDMProfileRedeliveryCampaignRunning MDMProfileRedeliveryCampaignStatus = "running"
+	// MDMProfileRedeliveryCampaignPaused is the status of a campaign that was
+	// paused by a user, no more hosts get the profile redelivered until it is
+	// resumed.
+	MDMProfileRedeliveryCampaignPaused MDMProfileRedeliveryCampaignStatus = "paused"
+	// MDMProfileRedeliveryCampaignCompleted is the status of a campaign that
+	// redelivered the profile to all of its hosts.
+	MDMProfileRedeliveryCampaignCompleted MDMProfileRedeliveryCampaignStatus = "completed"
+)
+
+// IsValid returns true if s is a known campaign status.
+func (s MDMProfileRedeliveryCampaignStatus) IsValid() bool {
+	switch s {
+	case MDMProfileRedeliveryCampaignRunning, MDMProfileRedeliveryCampaignPaused, MDMProfileRedeliveryCampaignCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// MaxMDMProfileRedeliveryHostsPerMinute is the maximum rate of a profile
+// redelivery campaign.
+const MaxMDMProfileRedeliveryHostsPerMinute = 10000
+
+// MDMProfileRedeliveryCampaign is a campaign that redelivers a configuration
+// profile to the hosts that have it installed, a batch of hosts at a time,
+// so that the MDM server and the hosts are not overwhelmed when a profile
+// must be resent to a large number of hosts.
+type MDMProfileRedeliveryCampaign struct {
+	ID          uint   `json:"id" db:"id"`
+	ProfileUUID string `json:"profile_uuid" db:"profile_uuid"`
+	ProfileName string `json:"profile_name" db:"profile_name"`
+	// TeamID is the team of the profile, nil for "No team".
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// HostsPerMinute is the number of hosts that get the profile redelivered
+	// per minute.
+	HostsPerMinute uint                               `json:"hosts_per_minute" db:"hosts_per_minute"`
+	Status         MDMProfileRedeliveryCampaignStatus `json:"status" db:"status"`
+	// TotalHosts is the number of hosts targeted by the campaign, and
+	// DeliveredHosts the number of those for which the profile has been
+	// queued for redelivery.
+	TotalHosts     uint `json:"total_hosts" db:"total_hosts"`
+	DeliveredHosts uint `json:"delivered_hosts" db:"delivered_hosts"`
+	// LastBatchAt is the last time a batch of hosts got the profile
+	// redelivered, nil if no batch was processed since the campaign was
+	// created or resumed.
+	LastBatchAt *time.Time `json:"last_batch_at" db:"last_batch_at"`
+	// CreatedByID is the ID of the user that created the campaign, nil if the
+	// user was deleted.
+	CreatedByID *uint `json:"created_by_id" db:"created_by_id"`
+
+	UpdateCreateTimestamps
+}
+
+// RemainingHosts returns the number of hosts that have not had the profile
+// redelivered yet.
+func (c *MDMProfileRedeliveryCampaign) RemainingHosts() uint {
+	if c.DeliveredHosts >= c.TotalHosts {
+		return 0
+	}
+	return c.TotalHosts - c.DeliveredHosts
+}
+
+// NextBatchSize returns the number of hosts that should get the profile
+// redelivered at time now to respect the rate of the campaign. It never
+// exceeds the rate, so that a campaign does not catch up with a burst after
+// a delay (e.g. when the server was down).
+func (c *MDMProfileRedeliveryCampaign) NextBatchSize(now time.Time) uint {
+	if c.Status != MDMProfileRedeliveryCampaignRunning || c.HostsPerMinute == 0 {
+		return 0
+	}
+	size := c.HostsPerMinute
+	if c.LastBatchAt != nil {
+		elapsed := now.Sub(*c.LastBatchAt)
+		if elapsed < time.Minute {
+			// allow partial batches, e.g. if the cron ran early
+			size = uint(math.Round(float64(c.HostsPerMinute) * elapsed.Minutes()))
+		}
+	}
+	if remaining := c.RemainingHosts(); size > remaining {
+		size = remaining
+	}
+	return size
+}
+
+// MDMProfileRedeliveryCampaignPayload is the payload to create a profile
+// redelivery campaign.
+type MDMProfileRedeliveryCampaignPayload struct {
+	ProfileUUID    string `json:"profile_uuid"`
+	HostsPerMinute uint   `json:"hosts_per_minute"`
+}
+
+// MDMProfileRedeliveryCampaignUpdate is the payload to modify a profile
+// redelivery campaign, i.e. to pause or resume it or to change its rate.
+type MDMProfileRedeliveryCampaignUpdate struct {
+	Status         *MDMProfileRedeliveryCampaignStatus `json:"status"`
+	HostsPerMinute *uint                               `json:"hosts_per_minute"`
+}
+
+// MDMProfileRedeliveryCampaignListOptions are the options to list profile
+// redelivery campaigns.
+type MDMProfileRedeliveryCampaignListOptions struct {
+	// TeamID filters the campaigns of the team's profiles, nil for the "No
+	// team" profiles.
+	TeamID *uint
+	// Status filters the campaigns by status, all campaigns are returned if
+	// empty.
+	Status MDMProfileRedeliveryCampaignStatus
+}
@@ -0,0 +1,80 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRedeliveryCampaignNextBatchSize(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		desc     string
+		campaign MDMProfileRedeliveryCampaign
+		want     uint
+	}{
+		{
+			desc:     "paused",
+			campaign: MDMProfileRedeliveryCampaign{Status: MDMProfileRedeliveryCampaignPaused, HostsPerMinute: 10, TotalHosts: 100},
+			want:     0,
+		},
+		{
+			desc:     "completed",
+			campaign: MDMProfileRedeliveryCampaign{Status: MDMProfileRedeliveryCampaignCompleted, HostsPerMinute: 10, TotalHosts: 100},
+			want:     0,
+		},
+		{
+			desc:     "first batch",
+			campaign: MDMProfileRedeliveryCampaign{Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100},
+			want:     10,
+		},
+		{
+			desc: "a minute after the last batch",
+			campaign: MDMProfileRedeliveryCampaign{
+				Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 10,
+				LastBatchAt: ptr.Time(now.Add(-time.Minute)),
+			},
+			want: 10,
+		},
+		{
+			desc: "slightly less than a minute after the last batch",
+			campaign: MDMProfileRedeliveryCampaign{
+				Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 10,
+				LastBatchAt: ptr.Time(now.Add(-59 * time.Second)),
+			},
+			want: 10,
+		},
+		{
+			desc: "half a minute after the last batch",
+			campaign: MDMProfileRedeliveryCampaign{
+				Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 10,
+				LastBatchAt: ptr.Time(now.Add(-30 * time.Second)),
+			},
+			want: 5,
+		},
+		{
+			desc: "long after the last batch does not catch up",
+			campaign: MDMProfileRedeliveryCampaign{
+				Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 10,
+				LastBatchAt: ptr.Time(now.Add(-time.Hour)),
+			},
+			want: 10,
+		},
+		{
+			desc: "fewer remaining hosts than the rate",
+			campaign: MDMProfileRedeliveryCampaign{
+				Status: MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 97,
+				LastBatchAt: ptr.Time(now.Add(-time.Minute)),
+			},
+			want: 3,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.want, c.campaign.NextBatchSize(now))
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package fleet
+
+import (
+	"fmt"
+	"time"
+)
+
+// MDMProfileRolloutStatus is the status of the staged rollout of a
+// configuration profile.
+type MDMProfileRolloutStatus string
+
+const (
+	// MDMProfileRolloutCanary is the status of a rollout that delivers the
+	// profile only to its canary hosts, until their verification results are
+	// known.
+	MDMProfileRolloutCanary MDMProfileRolloutStatus = "canary"
+	// MDMProfileRolloutCompleted is the status of a rollout that delivers the
+	// profile to all of its targeted hosts.
+	MDMProfileRolloutCompleted MDMProfileRolloutStatus = "completed"
+	// MDMProfileRolloutHalted is the status of a rollout that was stopped
+	// because too many canary hosts failed to install the profile, or by a
+	// user. The profile is not delivered to the hosts outside of the canary
+	// sample.
+	MDMProfileRolloutHalted MDMProfileRolloutStatus = "halted"
+)
+
+// IsValid returns true if s is a known rollout status.
+func (s MDMProfileRolloutStatus) IsValid() bool {
+	switch s {
+	case MDMProfileRolloutCanary, MDMProfileRolloutCompleted, MDMProfileRolloutHalted:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// DefaultMDMProfileRolloutTimeoutMinutes is the time that a rollout waits
+	// for the verification results of its canary hosts when no timeout is
+	// provided.
+	DefaultMDMProfileRolloutTimeoutMinutes = 24 * 60
+	// MaxMDMProfileRolloutTimeoutMinutes is the maximum time that a rollout
+	// can wait for the verification results of its canary hosts.
+	MaxMDMProfileRolloutTimeoutMinutes = 7 * 24 * 60
+)
+
+// MDMProfileRolloutSettings are the settings of the staged rollout of a
+// configuration profile.
+type MDMProfileRolloutSettings struct {
+	// CanaryPercentage is the percentage of the targeted hosts that get the
+	// profile first.
+	CanaryPercentage uint `json:"canary_percentage" db:"canary_percentage"`
+	// FailureThresholdPercentage is the maximum percentage of the canary
+	// hosts that can fail to install the profile for the rollout to continue
+	// to all the targeted hosts.
+	FailureThresholdPercentage uint `json:"failure_threshold_percentage" db:"failure_threshold_percentage"`
+	// TimeoutMinutes is the maximum time to wait for the verification results
+	// of all the canary hosts. When it expires, the rollout continues or halts
+	// based on the results received so far.
+	TimeoutMinutes uint `json:"timeout_minutes" db:"timeout_minutes"`
+}
+
+// Validate checks the settings and sets the default timeout if none is
+// provided.
+func (s *MDMProfileRolloutSettings) Validate() error {
+	if s.CanaryPercentage < 1 || s.CanaryPercentage > 99 {
+		return NewInvalidArgumentError("rollout.canary_percentage", "Must be between 1 and 99.")
+	}
+	if s.FailureThresholdPercentage > 100 {
+		return NewInvalidArgumentError("rollout.failure_threshold_percentage", "Must be between 0 and 100.")
+	}
+	if s.TimeoutMinutes > MaxMDMProfileRolloutTimeoutMinutes {
+		return NewInvalidArgumentError("rollout.timeout_minutes",
+			fmt.Sprintf("Must be at most %d.", MaxMDMProfileRolloutTimeoutMinutes))
+	}
+	if s.TimeoutMinutes == 0 {
+		s.TimeoutMinutes = DefaultMDMProfileRolloutTimeoutMinutes
+	}
+	return nil
+}
+
+// MDMProfileRollout is the staged rollout of a configuration profile. While
+// the rollout is in the canary status, the profile is only delivered to a
+// sample of its targeted hosts. Once the verification results of those
+// hosts are known, the rollout automatically continues to all the targeted
+// hosts or halts, based on its failure threshold.
+type MDMProfileRollout struct {
+	ProfileUUID string `json:"profile_uuid" db:"profile_uuid"`
+	ProfileName string `json:"profile_name" db:"profile_name"`
+	// TeamID is the team of the profile, nil for "No team".
+	TeamID *uint `json:"team_id" db:"team_id"`
+
+	MDMProfileRolloutSettings
+
+	Status MDMProfileRolloutStatus `json:"status" db:"status"`
+	// Reason explains why the rollout was completed or halted.
+	Reason string `json:"reason" db:"reason"`
+	// StartedAt is the time the canary stage started, and FinishedAt the time
+	// the rollout was completed or halted.
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at" db:"finished_at"`
+
+	// Results are the verification results of the canary hosts.
+	Results MDMProfileRolloutResults `json:"results" db:"-"`
+
+	UpdateCreateTimestamps
+}
+
+// MDMProfileRolloutResults are the verification results of the canary hosts
+// of a profile rollout.
+type MDMProfileRolloutResults struct {
+	// CanaryHosts is the number of targeted hosts that are part of the
+	// canary sample.
+	CanaryHosts uint `json:"canary_hosts" db:"canary_hosts"`
+	// VerifiedHosts and FailedHosts are the number of canary hosts for which
+	// the profile was verified or failed, the others are pending.
+	VerifiedHosts uint `json:"verified_hosts" db:"verified_hosts"`
+	FailedHosts   uint `json:"failed_hosts" db:"failed_hosts"`
+}
+
+// PendingHosts returns the number of canary hosts that have no verification
+// result yet.
+func (r MDMProfileRolloutResults) PendingHosts() uint {
+	if done := r.VerifiedHosts + r.FailedHosts; done < r.CanaryHosts {
+		return r.CanaryHosts - done
+	}
+	return 0
+}
+
+// Evaluate returns the status of a rollout in the canary status given the
+// verification results of its canary hosts at time now, along with the
+// reason of the status change. The status is unchanged until enough results
+// are known or the timeout expires.
+func (r *MDMProfileRollout) Evaluate(res MDMProfileRolloutResults, now time.Time) (MDMProfileRolloutStatus, string) {
+	if r.Status != MDMProfileRolloutCanary {
+		return r.Status, r.Reason
+	}
+
+	if res.CanaryHosts == 0 {
+		return MDMProfileRolloutCompleted, "No targeted hosts are part of the canary sample."
+	}
+
+	// halt as soon as the failures exceed the threshold, even if some results
+	// are still pending.
+	if res.FailedHosts*100 > r.FailureThresholdPercentage*res.CanaryHosts {
+		return MDMProfileRolloutHalted, fmt.Sprintf("The profile failed on %d of %d canary hosts, above the %d%% failure threshold.",
+			res.FailedHosts, res.CanaryHosts, r.FailureThresholdPercentage)
+	}
+	if res.PendingHosts() == 0 {
+		return MDMProfileRolloutCompleted, fmt.Sprintf("The profile was verified on %d of %d canary hosts.", res.VerifiedHosts, res.CanaryHosts)
+	}
+
+	if now.Sub(r.StartedAt) < time.Duration(r.TimeoutMinutes)*time.Minute {
+		return MDMProfileRolloutCanary, ""
+	}
+
+	// the timeout expired, decide based on the results received so far.
+	done := res.VerifiedHosts + res.FailedHosts
+	if done == 0 {
+		return MDMProfileRolloutHalted, fmt.Sprintf("No canary host reported a verification result within %d minutes.", r.TimeoutMinutes)
+	}
+	if res.FailedHosts*100 > r.FailureThresholdPercentage*done {
+		return MDMProfileRolloutHalted, fmt.Sprintf("The profile failed on %d of %d canary hosts that reported within %d minutes, above the %d%% failure threshold.",
+			res.FailedHosts, done, r.TimeoutMinutes, r.FailureThresholdPercentage)
+	}
+	return MDMProfileRolloutCompleted, fmt.Sprintf("The profile was verified on %d of %d canary hosts that reported within %d minutes.",
+		res.VerifiedHosts, done, r.TimeoutMinutes)
+}
+
+// MDMProfileRolloutUpdate is the payload to modify a profile rollout, i.e.
+// to continue it to all the targeted hosts, halt it or restart its canary
+// stage.
+type MDMProfileRolloutUpdate struct {
+	Status MDMProfileRolloutStatus `json:"status"`
+}
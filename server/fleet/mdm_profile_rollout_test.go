@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRolloutSettingsValidate(t *testing.T) {
+	cases := []struct {
+		desc        string
+		settings    MDMProfileRolloutSettings
+		wantErr     string
+		wantTimeout uint
+	}{
+		{"no canary", MDMProfileRolloutSettings{CanaryPercentage: 0}, "rollout.canary_percentage", 0},
+		{"all hosts in canary", MDMProfileRolloutSettings{CanaryPercentage: 100}, "rollout.canary_percentage", 0},
+		{"threshold too high", MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 101}, "rollout.failure_threshold_percentage", 0},
+		{"timeout too long", MDMProfileRolloutSettings{CanaryPercentage: 10, TimeoutMinutes: MaxMDMProfileRolloutTimeoutMinutes + 1}, "rollout.timeout_minutes", 0},
+		{"default timeout", MDMProfileRolloutSettings{CanaryPercentage: 10}, "", DefaultMDMProfileRolloutTimeoutMinutes},
+		{"custom timeout", MDMProfileRolloutSettings{CanaryPercentage: 99, FailureThresholdPercentage: 100, TimeoutMinutes: 60}, "", 60},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := c.settings.Validate()
+			if c.wantErr != "" {
+				require.ErrorContains(t, err, c.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.wantTimeout, c.settings.TimeoutMinutes)
+		})
+	}
+}
+
+func TestMDMProfileRolloutEvaluate(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Hour)
+	rollout := func(status MDMProfileRolloutStatus, timeout uint) *MDMProfileRollout {
+		return &MDMProfileRollout{
+			MDMProfileRolloutSettings: MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 20, TimeoutMinutes: timeout},
+			Status:                    status,
+			StartedAt:                 started,
+		}
+	}
+
+	cases := []struct {
+		desc    string
+		rollout *MDMProfileRollout
+		results MDMProfileRolloutResults
+		want    MDMProfileRolloutStatus
+	}{
+		{"halted stays halted", rollout(MDMProfileRolloutHalted, 120), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 10}, MDMProfileRolloutHalted},
+		{"completed stays completed", rollout(MDMProfileRolloutCompleted, 120), MDMProfileRolloutResults{CanaryHosts: 10, FailedHosts: 10}, MDMProfileRolloutCompleted},
+		{"no canary hosts", rollout(MDMProfileRolloutCanary, 120), MDMProfileRolloutResults{}, MDMProfileRolloutCompleted},
+		{"pending results", rollout(MDMProfileRolloutCanary, 120), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 5, FailedHosts: 1}, MDMProfileRolloutCanary},
+		{"failures at the threshold", rollout(MDMProfileRolloutCanary, 120), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 8, FailedHosts: 2}, MDMProfileRolloutCompleted},
+		{"failures above the threshold", rollout(MDMProfileRolloutCanary, 120), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 1, FailedHosts: 3}, MDMProfileRolloutHalted},
+		{"timeout without results", rollout(MDMProfileRolloutCanary, 30), MDMProfileRolloutResults{CanaryHosts: 10}, MDMProfileRolloutHalted},
+		{"timeout with few failures", rollout(MDMProfileRolloutCanary, 30), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 4, FailedHosts: 1}, MDMProfileRolloutCompleted},
+		{"timeout with many failures", rollout(MDMProfileRolloutCanary, 30), MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 3, FailedHosts: 1}, MDMProfileRolloutHalted},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, reason := c.rollout.Evaluate(c.results, now)
+			require.Equal(t, c.want, got)
+			if got != c.rollout.Status {
+				require.NotEmpty(t, reason)
+			}
+		})
+	}
+}
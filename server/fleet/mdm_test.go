@@ -315,6 +315,56 @@ func TestMDMProfileSpecsMatch(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "Same Labels Different Sets",
+			a: []fleet.MDMProfileSpec{
+				{Path: "path1", Labels: []string{"label1"}, LabelsExcludeAny: []string{"label2"}},
+			},
+			b: []fleet.MDMProfileSpec{
+				{Path: "path1", LabelsIncludeAny: []string{"label1"}, LabelsExcludeAny: []string{"label2"}},
+			},
+			expected: false,
+		},
+		{
+			name: "Label Sets Match",
+			a: []fleet.MDMProfileSpec{
+				{Path: "path1", Labels: []string{"label1"}, LabelsIncludeAny: []string{"label2", "label3"}, LabelsExcludeAny: []string{"label4"}},
+			},
+			b: []fleet.MDMProfileSpec{
+				{Path: "path1", Labels: []string{"label1"}, LabelsIncludeAny: []string{"label3", "label2"}, LabelsExcludeAny: []string{"label4"}},
+			},
+			expected: true,
+		},
+		{
+			name: "Rollout Match",
+			a: []fleet.MDMProfileSpec{
+				{Path: "path1", Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 5}},
+			},
+			b: []fleet.MDMProfileSpec{
+				{Path: "path1", Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 5}},
+			},
+			expected: true,
+		},
+		{
+			name: "Rollout Mismatch",
+			a: []fleet.MDMProfileSpec{
+				{Path: "path1", Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 5}},
+			},
+			b: []fleet.MDMProfileSpec{
+				{Path: "path1", Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 20, FailureThresholdPercentage: 5}},
+			},
+			expected: false,
+		},
+		{
+			name: "Rollout Removed",
+			a: []fleet.MDMProfileSpec{
+				{Path: "path1", Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10}},
+			},
+			b: []fleet.MDMProfileSpec{
+				{Path: "path1"},
+			},
+			expected: false,
+		},
 	}
 
 	for _, tc := range tests {
@@ -324,3 +374,67 @@ func TestMDMProfileSpecsMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestMDMProfileLabelTargetingValidate(t *testing.T) {
+	cases := []struct {
+		name      string
+		targeting fleet.MDMProfileLabelTargeting
+		wantErr   string
+	}{
+		{"empty", fleet.MDMProfileLabelTargeting{}, ""},
+		{"only labels", fleet.MDMProfileLabelTargeting{Labels: []string{"a", "b"}}, ""},
+		{
+			"disjoint sets",
+			fleet.MDMProfileLabelTargeting{Labels: []string{"a"}, LabelsIncludeAny: []string{"b", "c"}, LabelsExcludeAny: []string{"d"}},
+			"",
+		},
+		{"duplicate in same set", fleet.MDMProfileLabelTargeting{LabelsIncludeAny: []string{"a", "a"}}, ""},
+		{
+			"included and excluded",
+			fleet.MDMProfileLabelTargeting{Labels: []string{"a"}, LabelsExcludeAny: []string{"a"}},
+			`label "a" cannot be in both labels and labels_exclude_any`,
+		},
+		{
+			"include all and include any",
+			fleet.MDMProfileLabelTargeting{Labels: []string{"a"}, LabelsIncludeAny: []string{"b", "a"}},
+			`label "a" cannot be in both labels and labels_include_any`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.targeting.Validate()
+			if c.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestJoinSplitConfigurationProfileLabels(t *testing.T) {
+	includeAll := []fleet.ConfigurationProfileLabel{{LabelName: "a", LabelID: 1}}
+	includeAny := []fleet.ConfigurationProfileLabel{{LabelName: "b", LabelID: 2}, {LabelName: "c", LabelID: 3}}
+	excludeAny := []fleet.ConfigurationProfileLabel{{LabelName: "d", LabelID: 4}}
+
+	require.Nil(t, fleet.JoinConfigurationProfileLabels(nil, nil, nil))
+
+	joined := fleet.JoinConfigurationProfileLabels(includeAll, includeAny, excludeAny)
+	require.Len(t, joined, 4)
+	require.Equal(t, fleet.ConfigurationProfileLabel{LabelName: "a", LabelID: 1, RequireAll: true}, joined[0])
+	require.Equal(t, fleet.ConfigurationProfileLabel{LabelName: "b", LabelID: 2}, joined[1])
+	require.Equal(t, fleet.ConfigurationProfileLabel{LabelName: "d", LabelID: 4, Exclude: true}, joined[3])
+
+	gotAll, gotAny, gotExclude := fleet.SplitConfigurationProfileLabels(joined)
+	require.Equal(t, []string{"a"}, labelNames(gotAll))
+	require.Equal(t, []string{"b", "c"}, labelNames(gotAny))
+	require.Equal(t, []string{"d"}, labelNames(gotExclude))
+}
+
+func labelNames(labels []fleet.ConfigurationProfileLabel) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.LabelName)
+	}
+	return names
+}
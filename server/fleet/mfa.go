@@ -0,0 +1,202 @@
+package fleet
+
+import (
+	"net/http"
+	"time"
+)
+
+// mfaEnrollmentRoutes are the API routes that a user who must enroll a second
+// factor can still use, so that they can do that enrollment.
+var mfaEnrollmentRoutes = []apiTokenRoute{
+	newAPITokenRoute(`/me`, http.MethodGet),
+	newAPITokenRoute(`/me/mfa(?:/.*)?`),
+	newAPITokenRoute(`/logout`, http.MethodPost),
+	newAPITokenRoute(`/config`, http.MethodGet),
+	newAPITokenRoute(`/version`, http.MethodGet),
+}
+
+// MFAEnrollmentAllowsRequest returns true if a user who must enroll a second
+// factor is allowed to make a request with the provided HTTP method to the
+// provided URL path.
+func MFAEnrollmentAllowsRequest(method, path string) bool {
+	for _, r := range mfaEnrollmentRoutes {
+		if r.matches(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAMethod is a second authentication factor that a password-based user can
+// use to log in.
+type MFAMethod string
+
+const (
+	// MFAMethodTOTP is a time-based one-time password generated by an
+	// authenticator app.
+	MFAMethodTOTP MFAMethod = "totp"
+	// MFAMethodWebAuthn is a WebAuthn security key or platform authenticator.
+	MFAMethodWebAuthn MFAMethod = "webauthn"
+	// MFAMethodRecoveryCode is a single-use recovery code, for when the user
+	// lost access to their other factors.
+	MFAMethodRecoveryCode MFAMethod = "recovery_code"
+)
+
+// MFAChallengeExpiry is the time a user has to complete an MFA challenge
+// (second step of the login or WebAuthn registration).
+const MFAChallengeExpiry = 5 * time.Minute
+
+// UserTOTPSecret is the TOTP secret of a user. It is only used to log in once
+// it has been confirmed with a valid code.
+type UserTOTPSecret struct {
+	UserID      uint       `db:"user_id"`
+	Secret      string     `db:"secret"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+	// LastUsedStep is the last TOTP time step used to log in, codes for this
+	// step or earlier ones are rejected to prevent replays.
+	LastUsedStep int64     `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// UserWebAuthnCredential is a WebAuthn credential registered by a user.
+type UserWebAuthnCredential struct {
+	ID           uint       `json:"id" db:"id"`
+	UserID       uint       `json:"-" db:"user_id"`
+	Name         string     `json:"name" db:"name"`
+	CredentialID []byte     `json:"-" db:"credential_id"`
+	PublicKey    []byte     `json:"-" db:"public_key"`
+	SignCount    uint32     `json:"-" db:"sign_count"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+// MFAChallengePurpose is the step an MFA challenge is used for.
+type MFAChallengePurpose string
+
+const (
+	// MFAChallengePurposeLogin is the second step of a password login.
+	MFAChallengePurposeLogin MFAChallengePurpose = "login"
+	// MFAChallengePurposeWebAuthnRegistration is the registration of a new
+	// WebAuthn credential.
+	MFAChallengePurposeWebAuthnRegistration MFAChallengePurpose = "webauthn_registration"
+)
+
+// MFAChallenge is a pending, short-lived, MFA step for a user, identified by
+// a random token.
+type MFAChallenge struct {
+	Token   string              `db:"token"`
+	UserID  uint                `db:"user_id"`
+	Purpose MFAChallengePurpose `db:"purpose"`
+	// WebAuthnChallenge is the random challenge that a WebAuthn authenticator
+	// must sign, if any.
+	WebAuthnChallenge []byte    `db:"webauthn_challenge"`
+	CreatedAt         time.Time `db:"created_at"`
+	// Attempts is the number of second factors submitted for the challenge.
+	Attempts int `db:"attempts"`
+}
+
+// Expired returns true if the challenge can no longer be used at time now.
+func (c *MFAChallenge) Expired(now time.Time) bool {
+	return now.Sub(c.CreatedAt) >= MFAChallengeExpiry
+}
+
+// UserMFAStatus is the second factors configuration of a user.
+type UserMFAStatus struct {
+	// Enabled is true if the user must provide a second factor to log in.
+	Enabled bool `json:"enabled"`
+	// EnrollmentRequired is true if the organization requires MFA and the user
+	// has not enrolled a second factor yet.
+	EnrollmentRequired     bool                      `json:"enrollment_required"`
+	TOTPEnabled            bool                      `json:"totp_enabled"`
+	WebAuthnCredentials    []*UserWebAuthnCredential `json:"webauthn_credentials"`
+	RecoveryCodesRemaining int                       `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrollment is returned when a user starts enrolling an authenticator
+// app. The secret must be confirmed with a valid code before it can be used.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI of the secret, typically rendered as a QR code.
+	URI string `json:"uri"`
+}
+
+// WebAuthnRegistrationOptions is returned when a user starts registering a
+// WebAuthn credential.
+type WebAuthnRegistrationOptions struct {
+	// ChallengeToken identifies the registration, it must be sent back with
+	// the new credential.
+	ChallengeToken string `json:"challenge_token"`
+	// PublicKey are the options to pass to navigator.credentials.create.
+	PublicKey any `json:"public_key"`
+}
+
+// WebAuthnRegistrationPayload is the response of navigator.credentials.create
+// sent by the browser to register a new WebAuthn credential. Binary values are
+// base64url encoded.
+type WebAuthnRegistrationPayload struct {
+	ChallengeToken    string `json:"challenge_token"`
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AttestationObject string `json:"attestation_object"`
+}
+
+// WebAuthnAssertion is the response of navigator.credentials.get sent by the
+// browser to log in with a WebAuthn credential. Binary values are base64url
+// encoded.
+type WebAuthnAssertion struct {
+	CredentialID      string `json:"credential_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+}
+
+// MFALoginPayload is the payload of the second step of a password login.
+// Exactly one of the factors must be provided.
+type MFALoginPayload struct {
+	Token        string             `json:"mfa_token"`
+	TOTPCode     string             `json:"totp_code"`
+	RecoveryCode string             `json:"recovery_code"`
+	WebAuthn     *WebAuthnAssertion `json:"webauthn"`
+}
+
+// Validate validates the MFA login payload.
+func (p MFALoginPayload) Validate() error {
+	if p.Token == "" {
+		return NewInvalidArgumentError("mfa_token", "missing MFA token")
+	}
+	var count int
+	if p.TOTPCode != "" {
+		count++
+	}
+	if p.RecoveryCode != "" {
+		count++
+	}
+	if p.WebAuthn != nil {
+		count++
+	}
+	if count != 1 {
+		return NewInvalidArgumentError("mfa", "exactly one of totp_code, recovery_code or webauthn must be provided")
+	}
+	return nil
+}
+
+// MFARequiredError is returned by a successful password login when the user
+// must complete the login with a second factor.
+type MFARequiredError struct {
+	// Token identifies the pending login, it must be sent back with the
+	// second factor.
+	Token   string
+	Methods []MFAMethod
+	// WebAuthnOptions are the options to pass to navigator.credentials.get,
+	// set only if the user registered WebAuthn credentials.
+	WebAuthnOptions any
+}
+
+func (e *MFARequiredError) Error() string {
+	return "multi-factor authentication required"
+}
+
+// StatusCode implements the ErrWithStatusCode interface.
+func (e *MFARequiredError) StatusCode() int {
+	return http.StatusUnauthorized
+}
@@ -44,17 +44,25 @@ type nudgeUpdateElements struct {
 	MainHeader       string `json:"mainHeader"`
 }
 
-func NewNudgeConfig(macOSUpdates MacOSUpdates) (*NudgeConfig, error) {
+// NewNudgeConfig returns the Nudge configuration to enforce the macOS updates
+// settings on a host located in the loc timezone. The deadline is at 04:00 in
+// the host's local time, or in UTC if loc is nil (e.g. the host did not report
+// its timezone).
+func NewNudgeConfig(macOSUpdates MacOSUpdates, loc *time.Location) (*NudgeConfig, error) {
 	deadline, err := time.Parse("2006-01-02", macOSUpdates.Deadline.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	if loc == nil {
+		loc = time.UTC
+	}
+
 	// Per the spec, the exact deadline time is arbitrarily chosen to be
-	// 04:00:00 (UTC-8) until we allow users to customize it.
+	// 04:00:00 until we allow users to customize it.
 	//
 	// See https://github.com/fleetdm/fleet/issues/9013 for more details.
-	localizedDeadline := time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 4, 0, 0, 0, time.UTC)
+	localizedDeadline := time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 4, 0, 0, 0, loc)
 
 	return &NudgeConfig{
 		OSVersionRequirements: []nudgeOSVersionRequirements{{
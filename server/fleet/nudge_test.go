@@ -0,0 +1,29 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/optjson"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNudgeConfigDeadline(t *testing.T) {
+	updates := MacOSUpdates{MinimumVersion: optjson.SetString("14.4"), Deadline: optjson.SetString("2024-05-02")}
+
+	cfg, err := NewNudgeConfig(updates, nil)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 5, 2, 4, 0, 0, 0, time.UTC), cfg.OSVersionRequirements[0].RequiredInstallationDate)
+
+	// the deadline is at 04:00 in the host's local time
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	cfg, err = NewNudgeConfig(updates, loc)
+	require.NoError(t, err)
+	deadline := cfg.OSVersionRequirements[0].RequiredInstallationDate
+	require.True(t, deadline.Equal(time.Date(2024, 5, 2, 11, 0, 0, 0, time.UTC)))
+	require.Equal(t, "2024-05-02T04:00:00-07:00", deadline.Format(time.RFC3339))
+
+	_, err = NewNudgeConfig(MacOSUpdates{Deadline: optjson.SetString("nope")}, loc)
+	require.Error(t, err)
+}
@@ -1,6 +1,10 @@
 package fleet
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
 
 // OrbitConfigNotifications are notifications that the fleet server sends to
 // fleetd (orbit) so that it can run commands or more generally react to this
@@ -30,9 +34,35 @@ type OrbitConfigNotifications struct {
 	// haven't received a result yet.
 	PendingScriptExecutionIDs []string `json:"pending_script_execution_ids,omitempty"`
 
+	// PendingLogCollectionIDs lists the execution IDs of the log collection
+	// requests that are pending for the host.
+	PendingLogCollectionIDs []string `json:"pending_log_collection_ids,omitempty"`
+
+	// PendingFileRetrievalIDs lists the execution IDs of the file retrieval
+	// requests that are pending for the host.
+	PendingFileRetrievalIDs []string `json:"pending_file_retrieval_ids,omitempty"`
+
+	// PendingPprofCaptureIDs lists the execution IDs of the pprof capture
+	// requests that are pending for the host.
+	PendingPprofCaptureIDs []string `json:"pending_pprof_capture_ids,omitempty"`
+
+	// PendingRemoteQueryIDs lists the execution IDs of the remote query
+	// requests that are pending for the host.
+	PendingRemoteQueryIDs []string `json:"pending_remote_query_ids,omitempty"`
+
 	// EnforceBitLockerEncryption is sent as true if Windows MDM is
 	// enabled and the device should encrypt its disk volumes with BitLocker.
 	EnforceBitLockerEncryption bool `json:"enforce_bitlocker_encryption,omitempty"`
+
+	// RunLinuxDiskEncryptionEscrow is sent as true if disk encryption is
+	// enforced and the Linux device should escrow (or rotate) the passphrase of
+	// its LUKS encrypted root volume.
+	RunLinuxDiskEncryptionEscrow bool `json:"run_linux_disk_encryption_escrow,omitempty"`
+
+	// EnforceLinuxDiskEncryption is sent as true if disk encryption is
+	// enforced and the root volume of the Linux device is not encrypted, so
+	// that the user is guided to encrypt it.
+	EnforceLinuxDiskEncryption bool `json:"enforce_linux_disk_encryption,omitempty"`
 }
 
 type OrbitConfig struct {
@@ -44,6 +74,18 @@ type OrbitConfig struct {
 	//
 	// If UpdateChannels is nil it means the server isn't using/setting this feature.
 	UpdateChannels *OrbitUpdateChannels `json:"update_channels,omitempty"`
+	// WindowsService contains the recovery policies and the watchdog settings
+	// of the fleetd service on Windows hosts.
+	//
+	// If WindowsService is nil it means the server isn't using/setting this
+	// feature, or the host is not a Windows host.
+	WindowsService *OrbitWindowsServiceConfig `json:"windows_service,omitempty"`
+	// FeatureFlags contains the value of each of the orbit feature flags for
+	// the host, as computed from the targeting of the flags.
+	//
+	// If FeatureFlags is nil it means the server isn't using/setting this
+	// feature.
+	FeatureFlags map[string]bool `json:"feature_flags,omitempty"`
 }
 
 // OrbitUpdateChannels hold the update channels that can be configured in fleetd agents.
@@ -56,6 +98,78 @@ type OrbitUpdateChannels struct {
 	Desktop string `json:"desktop"`
 }
 
+// OrbitServiceRecoveryActionType is the type of action taken by the Windows
+// service control manager when the fleetd service fails.
+type OrbitServiceRecoveryActionType string
+
+const (
+	// OrbitServiceRecoveryActionRestart restarts the fleetd service.
+	OrbitServiceRecoveryActionRestart OrbitServiceRecoveryActionType = "restart"
+	// OrbitServiceRecoveryActionNone takes no action.
+	OrbitServiceRecoveryActionNone OrbitServiceRecoveryActionType = "none"
+)
+
+const (
+	// MaxOrbitServiceRecoveryActions is the maximum number of recovery
+	// actions, one for each of the first, second and subsequent failures.
+	MaxOrbitServiceRecoveryActions = 3
+	// MaxOrbitServiceRecoveryDelaySeconds is the maximum delay of a recovery
+	// action.
+	MaxOrbitServiceRecoveryDelaySeconds = 24 * 60 * 60
+	// MinOrbitWatchdogHeartbeatSeconds is the minimum watchdog heartbeat,
+	// fleetd checks in with the server every 30 seconds so a shorter
+	// heartbeat would restart healthy agents.
+	MinOrbitWatchdogHeartbeatSeconds = 60
+)
+
+// OrbitWindowsServiceConfig holds the recovery policies and the watchdog
+// settings of the fleetd service on Windows hosts.
+type OrbitWindowsServiceConfig struct {
+	// RecoveryActions are the actions taken by the service control manager on
+	// the first, second and subsequent failures of the fleetd service.
+	RecoveryActions []OrbitServiceRecoveryAction `json:"recovery_actions"`
+	// ResetPeriodSeconds is the time without failures after which the failure
+	// count is reset to 0.
+	ResetPeriodSeconds int `json:"reset_period_seconds"`
+	// WatchdogHeartbeatSeconds is the maximum time without a heartbeat of
+	// fleetd's main loop before the watchdog stops fleetd so that it is
+	// restarted by the service control manager. 0 disables the watchdog.
+	WatchdogHeartbeatSeconds int `json:"watchdog_heartbeat_seconds"`
+}
+
+// OrbitServiceRecoveryAction is a recovery action of the fleetd service.
+type OrbitServiceRecoveryAction struct {
+	Type OrbitServiceRecoveryActionType `json:"type"`
+	// DelaySeconds is the time to wait before taking the action.
+	DelaySeconds int `json:"delay_seconds"`
+}
+
+// Validate checks that the recovery actions and the watchdog settings are
+// valid.
+func (c OrbitWindowsServiceConfig) Validate() error {
+	if len(c.RecoveryActions) > MaxOrbitServiceRecoveryActions {
+		return fmt.Errorf("recovery_actions: at most %d actions can be set", MaxOrbitServiceRecoveryActions)
+	}
+	for i, a := range c.RecoveryActions {
+		switch a.Type {
+		case OrbitServiceRecoveryActionRestart, OrbitServiceRecoveryActionNone:
+		default:
+			return fmt.Errorf("recovery_actions[%d].type: invalid type %q, must be %q or %q", i, a.Type,
+				OrbitServiceRecoveryActionRestart, OrbitServiceRecoveryActionNone)
+		}
+		if a.DelaySeconds < 0 || a.DelaySeconds > MaxOrbitServiceRecoveryDelaySeconds {
+			return fmt.Errorf("recovery_actions[%d].delay_seconds: must be between 0 and %d", i, MaxOrbitServiceRecoveryDelaySeconds)
+		}
+	}
+	if c.ResetPeriodSeconds < 0 {
+		return errors.New("reset_period_seconds: must not be negative")
+	}
+	if c.WatchdogHeartbeatSeconds != 0 && c.WatchdogHeartbeatSeconds < MinOrbitWatchdogHeartbeatSeconds {
+		return fmt.Errorf("watchdog_heartbeat_seconds: must be 0 (disabled) or at least %d", MinOrbitWatchdogHeartbeatSeconds)
+	}
+	return nil
+}
+
 // OrbitHostInfo holds device information used during Orbit enroll.
 type OrbitHostInfo struct {
 	// HardwareUUID is the device's hardware UUID.
@@ -111,3 +225,19 @@ type OrbitHostDiskEncryptionKeyPayload struct {
 	EncryptionKey []byte `json:"encryption_key"`
 	ClientError   string `json:"client_error"`
 }
+
+// OrbitHostLUKSDataPayload contains the LUKS passphrase escrowed by a Linux
+// host and the key slot it was added to.
+type OrbitHostLUKSDataPayload struct {
+	Passphrase  string `json:"passphrase"`
+	KeySlot     *uint  `json:"key_slot"`
+	ClientError string `json:"client_error"`
+}
+
+// OrbitLinuxDiskEncryptionEnforcementPayload contains the state of the
+// enforcement of disk encryption reported by a Linux host whose root volume is
+// not encrypted.
+type OrbitLinuxDiskEncryptionEnforcementPayload struct {
+	Status LinuxDiskEncryptionEnforcementStatus `json:"status"`
+	Detail string                               `json:"detail"`
+}
@@ -0,0 +1,107 @@
+package fleet
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+)
+
+// MaxOrbitFeatureFlagNameLength is the maximum length of the name of an
+// orbit feature flag.
+const MaxOrbitFeatureFlagNameLength = 64
+
+var orbitFeatureFlagNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// OrbitFeatureFlag defines a feature flag sent to fleetd agents, used to
+// roll out risky agent behaviors gradually. The flag is computed for each
+// host by the server and sent in the orbit config.
+type OrbitFeatureFlag struct {
+	// Name is the unique name of the flag, in snake_case.
+	Name string `json:"name"`
+	// Enabled turns the flag on for the targeted hosts. A disabled flag is
+	// still sent to the hosts, as false.
+	Enabled bool `json:"enabled"`
+	// TeamIDs restricts the flag to the hosts of those teams, 0 targets the
+	// hosts with no team. If empty, all hosts are targeted.
+	TeamIDs []uint `json:"team_ids"`
+	// Percentage restricts the flag to that percentage of the targeted
+	// hosts. The hosts are picked deterministically from the flag name and
+	// the host UUID, so a host keeps the same value as the percentage grows.
+	// If nil, all the targeted hosts get the flag.
+	Percentage *int `json:"percentage"`
+}
+
+// EnabledForHost returns true if the flag is turned on for the host.
+func (f OrbitFeatureFlag) EnabledForHost(host *Host) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	if len(f.TeamIDs) > 0 {
+		var hostTeamID uint
+		if host.TeamID != nil {
+			hostTeamID = *host.TeamID
+		}
+		var inTeam bool
+		for _, id := range f.TeamIDs {
+			if id == hostTeamID {
+				inTeam = true
+				break
+			}
+		}
+		if !inTeam {
+			return false
+		}
+	}
+
+	if f.Percentage != nil {
+		return orbitFeatureFlagBucket(f.Name, host.UUID) < *f.Percentage
+	}
+	return true
+}
+
+// orbitFeatureFlagBucket returns the bucket (0 to 99) of the host for the
+// flag. The flag name is part of the hash so that the same hosts are not
+// always the first to get every flag.
+func orbitFeatureFlagBucket(name, hostUUID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + hostUUID))
+	return int(h.Sum32() % 100)
+}
+
+// OrbitFeatureFlagsForHost returns the value of each of the flags for the
+// host, or nil if no flag is defined.
+func OrbitFeatureFlagsForHost(flags []OrbitFeatureFlag, host *Host) map[string]bool {
+	if len(flags) == 0 {
+		return nil
+	}
+	res := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		res[f.Name] = f.EnabledForHost(host)
+	}
+	return res
+}
+
+// ValidateOrbitFeatureFlags validates the orbit feature flags of the app
+// config and records any error in invalid.
+func ValidateOrbitFeatureFlags(flags []OrbitFeatureFlag, invalid *InvalidArgumentError) {
+	seen := make(map[string]bool, len(flags))
+	for i, f := range flags {
+		key := fmt.Sprintf("orbit_feature_flags[%d]", i)
+		switch {
+		case f.Name == "":
+			invalid.Append(key+".name", "name is required")
+		case len(f.Name) > MaxOrbitFeatureFlagNameLength:
+			invalid.Append(key+".name", fmt.Sprintf("name cannot be longer than %d characters", MaxOrbitFeatureFlagNameLength))
+		case !orbitFeatureFlagNameRegexp.MatchString(f.Name):
+			invalid.Append(key+".name", "name must start with a letter and contain only lowercase letters, digits and underscores")
+		case seen[f.Name]:
+			invalid.Append(key+".name", fmt.Sprintf("duplicate name %q", f.Name))
+		}
+		seen[f.Name] = true
+
+		if f.Percentage != nil && (*f.Percentage < 0 || *f.Percentage > 100) {
+			invalid.Append(key+".percentage", "must be between 0 and 100")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package fleet
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOrbitFeatureFlags(t *testing.T) {
+	invalid := &InvalidArgumentError{}
+	ValidateOrbitFeatureFlags([]OrbitFeatureFlag{
+		{Name: "installer_queue", Enabled: true},
+		{Name: "new_tables2", TeamIDs: []uint{0, 1}, Percentage: ptr.Int(100)},
+	}, invalid)
+	require.False(t, invalid.HasErrors())
+
+	cases := []struct {
+		flag    OrbitFeatureFlag
+		wantErr string
+	}{
+		{OrbitFeatureFlag{}, "name is required"},
+		{OrbitFeatureFlag{Name: "New"}, "name must start with a letter"},
+		{OrbitFeatureFlag{Name: "new-tables"}, "name must start with a letter"},
+		{OrbitFeatureFlag{Name: string(make([]byte, MaxOrbitFeatureFlagNameLength+1))}, "name cannot be longer than"},
+		{OrbitFeatureFlag{Name: "new", Percentage: ptr.Int(-1)}, "must be between 0 and 100"},
+		{OrbitFeatureFlag{Name: "new", Percentage: ptr.Int(101)}, "must be between 0 and 100"},
+	}
+	for _, c := range cases {
+		invalid := &InvalidArgumentError{}
+		ValidateOrbitFeatureFlags([]OrbitFeatureFlag{c.flag}, invalid)
+		require.ErrorContains(t, invalid, c.wantErr, c.flag)
+	}
+
+	invalid = &InvalidArgumentError{}
+	ValidateOrbitFeatureFlags([]OrbitFeatureFlag{{Name: "new"}, {Name: "new"}}, invalid)
+	require.ErrorContains(t, invalid, `duplicate name "new"`)
+}
+
+func TestOrbitFeatureFlagEnabledForHost(t *testing.T) {
+	noTeamHost := &Host{UUID: "uuid-1"}
+	teamHost := &Host{UUID: "uuid-2", TeamID: ptr.Uint(2)}
+
+	require.False(t, OrbitFeatureFlag{Name: "f"}.EnabledForHost(noTeamHost))
+	require.True(t, OrbitFeatureFlag{Name: "f", Enabled: true}.EnabledForHost(noTeamHost))
+	require.True(t, OrbitFeatureFlag{Name: "f", Enabled: true}.EnabledForHost(teamHost))
+
+	// team targeting, 0 is no team
+	require.True(t, OrbitFeatureFlag{Name: "f", Enabled: true, TeamIDs: []uint{0}}.EnabledForHost(noTeamHost))
+	require.False(t, OrbitFeatureFlag{Name: "f", Enabled: true, TeamIDs: []uint{0}}.EnabledForHost(teamHost))
+	require.False(t, OrbitFeatureFlag{Name: "f", Enabled: true, TeamIDs: []uint{1, 2}}.EnabledForHost(noTeamHost))
+	require.True(t, OrbitFeatureFlag{Name: "f", Enabled: true, TeamIDs: []uint{1, 2}}.EnabledForHost(teamHost))
+
+	// percentage targeting
+	require.False(t, OrbitFeatureFlag{Name: "f", Enabled: true, Percentage: ptr.Int(0)}.EnabledForHost(teamHost))
+	require.True(t, OrbitFeatureFlag{Name: "f", Enabled: true, Percentage: ptr.Int(100)}.EnabledForHost(teamHost))
+
+	const numHosts = 10000
+	hosts := make([]*Host, numHosts)
+	for i := range hosts {
+		hosts[i] = &Host{UUID: fmt.Sprintf("host-%d", i)}
+	}
+	countEnabled := func(f OrbitFeatureFlag) (n int, enabled map[string]bool) {
+		enabled = make(map[string]bool)
+		for _, h := range hosts {
+			if f.EnabledForHost(h) {
+				n++
+				enabled[h.UUID] = true
+			}
+		}
+		return n, enabled
+	}
+	n10, enabled10 := countEnabled(OrbitFeatureFlag{Name: "f", Enabled: true, Percentage: ptr.Int(10)})
+	require.InDelta(t, numHosts/10, n10, numHosts/50)
+	n50, enabled50 := countEnabled(OrbitFeatureFlag{Name: "f", Enabled: true, Percentage: ptr.Int(50)})
+	require.InDelta(t, numHosts/2, n50, numHosts/50)
+
+	// the hosts of a smaller percentage stay in a larger one
+	for uuid := range enabled10 {
+		require.True(t, enabled50[uuid], uuid)
+	}
+
+	// another flag picks other hosts
+	_, otherEnabled10 := countEnabled(OrbitFeatureFlag{Name: "g", Enabled: true, Percentage: ptr.Int(10)})
+	require.NotEqual(t, enabled10, otherEnabled10)
+}
+
+func TestOrbitFeatureFlagsForHost(t *testing.T) {
+	host := &Host{UUID: "uuid-1"}
+	require.Nil(t, OrbitFeatureFlagsForHost(nil, host))
+	require.Equal(t, map[string]bool{"on": true, "off": false}, OrbitFeatureFlagsForHost([]OrbitFeatureFlag{
+		{Name: "on", Enabled: true},
+		{Name: "off"},
+	}, host))
+}
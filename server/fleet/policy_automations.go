@@ -0,0 +1,142 @@
+package fleet
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// List of the automation types supported for failing policies.
+const (
+	PolicyAutomationWebhook = "webhook"
+	PolicyAutomationJira    = "jira"
+	PolicyAutomationZendesk = "zendesk"
+)
+
+// ActiveFailingPolicyAutomation returns the automation type enabled for
+// failing policies with the given webhook settings and integrations, or an
+// empty string if none is enabled.
+func ActiveFailingPolicyAutomation(webhook FailingPoliciesWebhookSettings, intgs Integrations) string {
+	// only one automation (i.e. webhook or integration) can be enabled at a
+	// time, enforced when updating the appconfig or the team config.
+	if webhook.Enable {
+		return PolicyAutomationWebhook
+	}
+
+	// check for jira integrations
+	for _, j := range intgs.Jira {
+		if j.EnableFailingPolicies {
+			return PolicyAutomationJira
+		}
+	}
+
+	// check for zendesk integrations
+	for _, z := range intgs.Zendesk {
+		if z.EnableFailingPolicies {
+			return PolicyAutomationZendesk
+		}
+	}
+	return ""
+}
+
+// FailingPoliciesWebhookPayload is the payload POSTed to the failing policies
+// webhook, for a batch of the hosts failing the policy.
+type FailingPoliciesWebhookPayload struct {
+	Timestamp    time.Time                    `json:"timestamp"`
+	Policy       *Policy                      `json:"policy"`
+	FailingHosts []FailingPoliciesWebhookHost `json:"hosts"`
+}
+
+// FailingPoliciesWebhookHost is a host of the failing policies webhook
+// payload.
+type FailingPoliciesWebhookHost struct {
+	ID          uint   `json:"id"`
+	Hostname    string `json:"hostname"`
+	DisplayName string `json:"display_name"`
+	URL         string `json:"url"`
+	// HealthScore is the health score of the host, nil if it was not computed
+	// yet.
+	HealthScore *int `json:"health_score,omitempty"`
+}
+
+// BatchPolicySetHosts splits the hosts failing a policy in the batches of
+// hostBatchSize hosts sent to the failing policies webhook, a single batch if
+// hostBatchSize is 0.
+func BatchPolicySetHosts(hosts []PolicySetHost, hostBatchSize int) [][]PolicySetHost {
+	if hostBatchSize <= 0 {
+		hostBatchSize = len(hosts)
+	}
+	var batches [][]PolicySetHost
+	for i := 0; i < len(hosts); i += hostBatchSize {
+		end := min(i+hostBatchSize, len(hosts))
+		batches = append(batches, hosts[i:end])
+	}
+	return batches
+}
+
+// NewFailingPoliciesWebhookPayload returns the payload to POST to the failing
+// policies webhook for a batch of hosts. The health scores of the hosts,
+// indexed by host ID, are included when available.
+func NewFailingPoliciesWebhookPayload(policy *Policy, batch []PolicySetHost, healthScores map[uint]*HostHealthScore, serverURL *url.URL, now time.Time) FailingPoliciesWebhookPayload {
+	failingHosts := make([]FailingPoliciesWebhookHost, len(batch))
+	for i, host := range batch {
+		u := *serverURL
+		u.Path = path.Join(serverURL.Path, "hosts", strconv.FormatUint(uint64(host.ID), 10))
+		failingHosts[i] = FailingPoliciesWebhookHost{
+			ID:          host.ID,
+			Hostname:    host.Hostname,
+			DisplayName: host.DisplayName,
+			URL:         u.String(),
+		}
+		if hs := healthScores[host.ID]; hs != nil {
+			failingHosts[i].HealthScore = &hs.Score
+		}
+	}
+	return FailingPoliciesWebhookPayload{
+		Timestamp:    now,
+		Policy:       policy,
+		FailingHosts: failingHosts,
+	}
+}
+
+// PolicyAutomationDryRunPayload is the automation configuration to simulate
+// for a policy. If AutomationType is empty, the automation currently enabled
+// for the policy's team (or globally) is simulated.
+type PolicyAutomationDryRunPayload struct {
+	AutomationType string `json:"automation_type"`
+	// DestinationURL and HostBatchSize are for the webhook automation only,
+	// the configured webhook settings are used if empty.
+	DestinationURL string `json:"destination_url"`
+	HostBatchSize  *int   `json:"host_batch_size"`
+}
+
+// PolicyAutomationDryRunResult is the result of the simulation of an
+// automation for a policy, i.e. the hosts that would trigger it and the
+// webhook requests or tickets that would be created for them.
+type PolicyAutomationDryRunResult struct {
+	PolicyID       uint   `json:"policy_id"`
+	AutomationType string `json:"automation_type"`
+	// PolicyEnabled is true if the automation is currently enabled for the
+	// policy, i.e. it runs the next time the automations are triggered.
+	PolicyEnabled bool `json:"policy_enabled"`
+	// HostCount is the number of hosts failing the policy that would trigger
+	// the automation.
+	HostCount       int                                    `json:"host_count"`
+	WebhookRequests []PolicyAutomationDryRunWebhookRequest `json:"webhook_requests,omitempty"`
+	Tickets         []PolicyAutomationDryRunTicket         `json:"tickets,omitempty"`
+}
+
+// PolicyAutomationDryRunWebhookRequest is a request that the failing policies
+// webhook automation would send.
+type PolicyAutomationDryRunWebhookRequest struct {
+	URL     string                        `json:"url"`
+	Payload FailingPoliciesWebhookPayload `json:"payload"`
+}
+
+// PolicyAutomationDryRunTicket is a Jira issue or Zendesk ticket that the
+// failing policies integration automation would create.
+type PolicyAutomationDryRunTicket struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+}
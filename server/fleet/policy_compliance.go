@@ -0,0 +1,45 @@
+package fleet
+
+import "time"
+
+const (
+	// PolicyComplianceHistoryRetention is how long the daily policy compliance
+	// counts are kept.
+	PolicyComplianceHistoryRetention = 2 * 365 * 24 * time.Hour
+	// PolicyComplianceTrendsDefaultDays is the default number of days covered
+	// by the policy compliance trends.
+	PolicyComplianceTrendsDefaultDays = 30
+)
+
+// PolicyComplianceTrendsOptions are the options to get the policy compliance
+// trends.
+type PolicyComplianceTrendsOptions struct {
+	// TeamID restricts the trends to the hosts of the team, for the team's
+	// policies and the global policies it inherits. If nil, the trends are
+	// those of the global policies on all hosts.
+	TeamID *uint
+	// PolicyID restricts the trends to a single policy.
+	PolicyID *uint
+	// StartDate and EndDate are the first and last days (in UTC) of the
+	// trends, both included.
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// PolicyComplianceDay is the number of passing and failing hosts for the
+// policies on a given day.
+type PolicyComplianceDay struct {
+	Date             time.Time `json:"date" db:"date"`
+	PassingHostCount uint      `json:"passing_host_count" db:"passing_host_count"`
+	FailingHostCount uint      `json:"failing_host_count" db:"failing_host_count"`
+}
+
+// PolicyComplianceTrends is the daily policy compliance over a range of days.
+// The days for which no counts were recorded are omitted.
+type PolicyComplianceTrends struct {
+	TeamID    *uint                 `json:"team_id"`
+	PolicyID  *uint                 `json:"policy_id"`
+	StartDate time.Time             `json:"start_date"`
+	EndDate   time.Time             `json:"end_date"`
+	Days      []PolicyComplianceDay `json:"days"`
+}
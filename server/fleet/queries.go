@@ -323,6 +323,118 @@ func verifyQueryPlatforms(platforms string) error {
 	return nil
 }
 
+// QueryValidationPayload is the SQL query to validate before saving it as a
+// query or a policy.
+type QueryValidationPayload struct {
+	// Query is the osquery SQL query.
+	Query string `json:"query"`
+	// Platform is the comma-separated list of platforms targeted by the query,
+	// empty if it targets all platforms.
+	Platform string `json:"platform"`
+}
+
+// Verify verifies the fields of the payload.
+func (p QueryValidationPayload) Verify() error {
+	if err := verifyQuerySQL(p.Query); err != nil {
+		return err
+	}
+	return verifyQueryPlatforms(p.Platform)
+}
+
+// Platforms returns the platforms targeted by the query.
+func (p QueryValidationPayload) Platforms() []string {
+	var platforms []string
+	for _, platform := range strings.Split(p.Platform, ",") {
+		if platform = strings.TrimSpace(platform); platform != "" {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms
+}
+
+// QueryValidationIssueType is the type of a problem found when validating a
+// SQL query.
+type QueryValidationIssueType string
+
+const (
+	// QueryValidationSyntaxError is a query that cannot be parsed.
+	QueryValidationSyntaxError QueryValidationIssueType = "syntax_error"
+	// QueryValidationNotSelect is a query that is not a SELECT statement.
+	QueryValidationNotSelect QueryValidationIssueType = "not_select"
+	// QueryValidationUnknownTable is a table that is neither an osquery table nor
+	// a fleetd table.
+	QueryValidationUnknownTable QueryValidationIssueType = "unknown_table"
+	// QueryValidationUnknownColumn is a column that does not exist in the table.
+	QueryValidationUnknownColumn QueryValidationIssueType = "unknown_column"
+	// QueryValidationPlatformMismatch is a table or column that is not available
+	// on a platform targeted by the query.
+	QueryValidationPlatformMismatch QueryValidationIssueType = "platform_mismatch"
+	// QueryValidationMissingRequiredColumn is a table queried without a
+	// constraint on any of its required columns, which returns no rows.
+	QueryValidationMissingRequiredColumn QueryValidationIssueType = "missing_required_column"
+	// QueryValidationEventedTable is an evented table, which only returns rows
+	// if osquery events are enabled.
+	QueryValidationEventedTable QueryValidationIssueType = "evented_table"
+)
+
+// QueryValidationIssue is a problem found when validating a SQL query.
+type QueryValidationIssue struct {
+	Type     QueryValidationIssueType `json:"type"`
+	Table    string                   `json:"table,omitempty"`
+	Column   string                   `json:"column,omitempty"`
+	Platform string                   `json:"platform,omitempty"`
+	Message  string                   `json:"message"`
+}
+
+// QueryDiscoveryQuery is the query that determines whether a table that is
+// not a core osquery table is available on a host.
+type QueryDiscoveryQuery struct {
+	Table string `json:"table"`
+	Query string `json:"query"`
+}
+
+// QueryValidationResult is the result of the validation of a SQL query.
+type QueryValidationResult struct {
+	// Valid is true if no errors were found, the query may still have
+	// warnings.
+	Valid    bool                   `json:"valid"`
+	Errors   []QueryValidationIssue `json:"errors"`
+	Warnings []QueryValidationIssue `json:"warnings"`
+	// Tables are the tables queried.
+	Tables []string `json:"tables"`
+	// Platforms are the platforms on which all the queried tables are
+	// available.
+	Platforms []string `json:"platforms"`
+	// DiscoveryQueries are the queries that determine whether the queried
+	// tables that are not core osquery tables are available on a host.
+	DiscoveryQueries []QueryDiscoveryQuery `json:"discovery_queries"`
+}
+
+// OsquerySchema is the authz type used to check access control to the
+// osquery schema catalog.
+type OsquerySchema struct{}
+
+// AuthzType implements authz.AuthzTyper.
+func (s *OsquerySchema) AuthzType() string {
+	return "osquery_schema"
+}
+
+// OsquerySchemaOptions are the options to list the tables of the osquery
+// schema catalog.
+type OsquerySchemaOptions struct {
+	// Platform restricts the tables to the ones available on the platform
+	// ("darwin", "windows", "linux" or "chrome"), all tables if empty.
+	Platform string `query:"platform,optional"`
+}
+
+// Verify verifies the options.
+func (o OsquerySchemaOptions) Verify() error {
+	if strings.Contains(o.Platform, ",") {
+		return ErrQueryInvalidPlatform
+	}
+	return verifyQueryPlatforms(o.Platform)
+}
+
 const (
 	QueryKind = "query"
 )
@@ -0,0 +1,169 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// QueryPostProcessingFunction is a function applied by the server to the
+// results of a live query campaign, to return an aggregate of the results
+// instead of the raw rows.
+type QueryPostProcessingFunction string
+
+const (
+	// QueryPostProcessingCount counts the rows returned by the hosts.
+	QueryPostProcessingCount QueryPostProcessingFunction = "count"
+	// QueryPostProcessingGroupBy counts the rows returned by the hosts for
+	// each value of a column.
+	QueryPostProcessingGroupBy QueryPostProcessingFunction = "group_by"
+	// QueryPostProcessingDistinct lists the distinct values of a column in
+	// the rows returned by the hosts.
+	QueryPostProcessingDistinct QueryPostProcessingFunction = "distinct"
+)
+
+// MaxQueryPostProcessingValues is the maximum number of groups or distinct
+// values kept by the group_by and distinct post-processing functions.
+const MaxQueryPostProcessingValues = 10000
+
+// QueryPostProcessing is the post-processing applied to the results of a live
+// query campaign.
+type QueryPostProcessing struct {
+	Function QueryPostProcessingFunction `json:"function"`
+	// Column is the column that the group_by and distinct functions apply
+	// to. It must not be set for the count function.
+	Column string `json:"column,omitempty"`
+}
+
+// Validate checks that the function is supported and that a column is set
+// for the functions that require it.
+func (p *QueryPostProcessing) Validate() error {
+	switch p.Function {
+	case QueryPostProcessingCount:
+		if p.Column != "" {
+			return NewInvalidArgumentError("post_processing.column", "column is not supported by the count function")
+		}
+	case QueryPostProcessingGroupBy, QueryPostProcessingDistinct:
+		if p.Column == "" {
+			return NewInvalidArgumentError("post_processing.column", fmt.Sprintf("column is required by the %s function", p.Function))
+		}
+	default:
+		return NewInvalidArgumentError("post_processing.function", fmt.Sprintf("unsupported function %q", p.Function))
+	}
+	return nil
+}
+
+// Scan implements the sql.Scanner interface
+func (p *QueryPostProcessing) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (p QueryPostProcessing) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// QueryResultsGroup is the number of rows with a value of the column of a
+// group_by post-processing.
+type QueryResultsGroup struct {
+	Value string `json:"value"`
+	Count uint   `json:"count"`
+}
+
+// QueryResultsAggregate is the aggregate of the results of a live query
+// campaign with post-processing, computed as the results are received.
+type QueryResultsAggregate struct {
+	QueryPostProcessing
+	// HostsResponded is the number of hosts that returned results.
+	HostsResponded uint `json:"hosts_responded"`
+	// HostsFailed is the number of hosts that returned an error.
+	HostsFailed uint `json:"hosts_failed"`
+	// Rows is the number of rows returned by the hosts.
+	Rows uint `json:"rows"`
+	// Groups are the groups of a group_by post-processing, sorted by
+	// decreasing count.
+	Groups []QueryResultsGroup `json:"groups,omitempty"`
+	// Values are the distinct values of a distinct post-processing, sorted.
+	Values []string `json:"values,omitempty"`
+	// Truncated is true if some groups or values were dropped because there
+	// were more than MaxQueryPostProcessingValues of them.
+	Truncated bool `json:"truncated"`
+	// Cursor is the highest cursor of the results in the aggregate, it is
+	// used by clients to resume the campaign.
+	Cursor uint64 `json:"cursor,omitempty"`
+
+	counts map[string]uint
+}
+
+// NewQueryResultsAggregate returns an empty aggregate for the post-processing.
+func NewQueryResultsAggregate(pp QueryPostProcessing) *QueryResultsAggregate {
+	a := &QueryResultsAggregate{QueryPostProcessing: pp}
+	if pp.Function != QueryPostProcessingCount {
+		a.counts = make(map[string]uint)
+	}
+	return a
+}
+
+// Add adds the result of a host to the aggregate. The rows that don't have
+// the column of the post-processing are only counted in Rows.
+func (a *QueryResultsAggregate) Add(res DistributedQueryResult) {
+	a.HostsResponded++
+	if res.Cursor > a.Cursor {
+		a.Cursor = res.Cursor
+	}
+	if res.Error != nil {
+		a.HostsFailed++
+	}
+	for _, row := range res.Rows {
+		if row == nil {
+			continue
+		}
+		a.Rows++
+		if a.counts == nil {
+			continue
+		}
+		v, ok := row[a.Column]
+		if !ok {
+			continue
+		}
+		if _, seen := a.counts[v]; !seen && len(a.counts) >= MaxQueryPostProcessingValues {
+			a.Truncated = true
+			continue
+		}
+		a.counts[v]++
+	}
+}
+
+// Summarize sets the groups or values of the aggregate from the results added
+// so far, so that it can be sent to the client.
+func (a *QueryResultsAggregate) Summarize() {
+	switch a.Function {
+	case QueryPostProcessingGroupBy:
+		a.Groups = make([]QueryResultsGroup, 0, len(a.counts))
+		for v, n := range a.counts {
+			a.Groups = append(a.Groups, QueryResultsGroup{Value: v, Count: n})
+		}
+		sort.Slice(a.Groups, func(i, j int) bool {
+			if a.Groups[i].Count != a.Groups[j].Count {
+				return a.Groups[i].Count > a.Groups[j].Count
+			}
+			return a.Groups[i].Value < a.Groups[j].Value
+		})
+	case QueryPostProcessingDistinct:
+		a.Values = make([]string, 0, len(a.counts))
+		for v := range a.counts {
+			a.Values = append(a.Values, v)
+		}
+		sort.Strings(a.Values)
+	}
+}
@@ -0,0 +1,97 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPostProcessingValidate(t *testing.T) {
+	cases := []struct {
+		pp      QueryPostProcessing
+		wantErr string
+	}{
+		{QueryPostProcessing{Function: QueryPostProcessingCount}, ""},
+		{QueryPostProcessing{Function: QueryPostProcessingCount, Column: "name"}, "column is not supported by the count function"},
+		{QueryPostProcessing{Function: QueryPostProcessingGroupBy, Column: "name"}, ""},
+		{QueryPostProcessing{Function: QueryPostProcessingGroupBy}, "column is required by the group_by function"},
+		{QueryPostProcessing{Function: QueryPostProcessingDistinct, Column: "name"}, ""},
+		{QueryPostProcessing{Function: QueryPostProcessingDistinct}, "column is required by the distinct function"},
+		{QueryPostProcessing{Function: "sum", Column: "name"}, `unsupported function "sum"`},
+		{QueryPostProcessing{}, `unsupported function ""`},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%s", c.pp.Function, c.pp.Column), func(t *testing.T) {
+			err := c.pp.Validate()
+			if c.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryResultsAggregate(t *testing.T) {
+	results := []DistributedQueryResult{
+		{Host: ResultHostData{ID: 1}, Rows: []map[string]string{{"name": "a"}, {"name": "b"}}, Cursor: 1},
+		{Host: ResultHostData{ID: 2}, Rows: []map[string]string{{"name": "b"}, nil, {"other": "c"}}, Cursor: 3},
+		{Host: ResultHostData{ID: 3}, Error: ptr.String("no such table"), Cursor: 2},
+		{Host: ResultHostData{ID: 4}, Rows: []map[string]string{{"name": "c"}, {"name": "b"}}},
+	}
+	aggregate := func(pp QueryPostProcessing) *QueryResultsAggregate {
+		a := NewQueryResultsAggregate(pp)
+		for _, res := range results {
+			a.Add(res)
+		}
+		a.Summarize()
+		return a
+	}
+
+	t.Run("count", func(t *testing.T) {
+		a := aggregate(QueryPostProcessing{Function: QueryPostProcessingCount})
+		require.Equal(t, uint(4), a.HostsResponded)
+		require.Equal(t, uint(1), a.HostsFailed)
+		require.Equal(t, uint(6), a.Rows)
+		require.Equal(t, uint64(3), a.Cursor)
+		require.Nil(t, a.Groups)
+		require.Nil(t, a.Values)
+
+		b, err := json.Marshal(a)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"function": "count", "hosts_responded": 4, "hosts_failed": 1, "rows": 6, "truncated": false, "cursor": 3}`, string(b))
+	})
+
+	t.Run("group_by", func(t *testing.T) {
+		a := aggregate(QueryPostProcessing{Function: QueryPostProcessingGroupBy, Column: "name"})
+		require.Equal(t, uint(6), a.Rows)
+		require.Equal(t, []QueryResultsGroup{{Value: "b", Count: 3}, {Value: "a", Count: 1}, {Value: "c", Count: 1}}, a.Groups)
+		require.Nil(t, a.Values)
+		require.False(t, a.Truncated)
+	})
+
+	t.Run("distinct", func(t *testing.T) {
+		a := aggregate(QueryPostProcessing{Function: QueryPostProcessingDistinct, Column: "name"})
+		require.Equal(t, []string{"a", "b", "c"}, a.Values)
+		require.Nil(t, a.Groups)
+		require.False(t, a.Truncated)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		a := NewQueryResultsAggregate(QueryPostProcessing{Function: QueryPostProcessingDistinct, Column: "name"})
+		rows := make([]map[string]string, 0, MaxQueryPostProcessingValues+1)
+		for i := 0; i <= MaxQueryPostProcessingValues; i++ {
+			rows = append(rows, map[string]string{"name": fmt.Sprint(i)})
+		}
+		a.Add(DistributedQueryResult{Rows: rows})
+		// a known value is still counted
+		a.Add(DistributedQueryResult{Rows: []map[string]string{{"name": "0"}}})
+		a.Summarize()
+		require.True(t, a.Truncated)
+		require.Len(t, a.Values, MaxQueryPostProcessingValues)
+		require.Equal(t, uint(MaxQueryPostProcessingValues+2), a.Rows)
+	})
+}
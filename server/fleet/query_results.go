@@ -17,7 +17,34 @@ type QueryResultStore interface {
 	// DistributedQueryResult or error
 	ReadChannel(ctx context.Context, query DistributedQueryCampaign) (<-chan interface{}, error)
 
+	// ReadChannelFromCursor is like ReadChannel, but the buffered results
+	// with a cursor greater than cursor are sent on the channel first,
+	// preceded by a QueryResultsReplay value describing them. Channel values
+	// should be either QueryResultsReplay, DistributedQueryResult or error.
+	ReadChannelFromCursor(ctx context.Context, query DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error)
+
+	// DetachCampaign records that the results of the campaign are not read
+	// anymore, but that a client may resume reading them. The results written
+	// while the campaign is detached are buffered instead of being rejected
+	// as having no subscriber, until the results buffer expires. It returns
+	// false if the store does not buffer results, in which case the campaign
+	// cannot be resumed.
+	DetachCampaign(ctx context.Context, campaignID uint) (bool, error)
+
 	// HealthCheck returns nil if the store is functioning properly, or an
 	// error describing the problem.
 	HealthCheck() error
 }
+
+// QueryResultsReplay describes the buffered results that are replayed when a
+// client resumes reading the results of a campaign.
+type QueryResultsReplay struct {
+	// Cursor is the cursor from which the results are replayed.
+	Cursor uint64 `json:"cursor"`
+	// Count is the number of replayed results.
+	Count int `json:"count"`
+	// Truncated is true if some results written after the cursor are not
+	// buffered anymore (the buffer is full or has expired), so they cannot be
+	// replayed.
+	Truncated bool `json:"truncated"`
+}
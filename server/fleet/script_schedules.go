@@ -0,0 +1,107 @@
+package fleet
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ScriptSchedule runs a saved script every day at a time of day that is
+// expressed in the local timezone of each host, so that a script scheduled at
+// 02:00 runs at 2am on every host, wherever it is located.
+type ScriptSchedule struct {
+	ID       uint `json:"id" db:"id"`
+	ScriptID uint `json:"script_id" db:"script_id"`
+	// ScriptName and TeamID are the name and team of the script, the script
+	// runs on the hosts of that team (or no team).
+	ScriptName string `json:"script_name" db:"script_name"`
+	TeamID     *uint  `json:"team_id" db:"team_id"`
+	// LocalTime is the time of day in the 24-hour "HH:MM" format at which the
+	// script runs, in the local timezone of each host.
+	LocalTime string `json:"local_time" db:"local_time"`
+	// AuthorID is the id of the user that created the schedule.
+	AuthorID  *uint     `json:"author_id" db:"author_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ScriptScheduleRunWindow is the duration after the local time of a schedule
+// during which the scheduled script is queued on a host. Past that window,
+// the run of that day is skipped, e.g. when the schedule is created later in
+// the day than its local time.
+const ScriptScheduleRunWindow = time.Hour
+
+// ParseScheduleLocalTime parses a time of day in the 24-hour "HH:MM" format.
+func ParseScheduleLocalTime(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil || len(s) != len("15:04") {
+		return 0, 0, fmt.Errorf("invalid time of day %q, must be in the HH:MM format", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ScheduleDueDate returns the local date ("YYYY-MM-DD") of the run of a
+// schedule at localTime that is due at now on a host in the loc timezone. It
+// returns false if no run is due at now, that is, if now is not within
+// ScriptScheduleRunWindow of localTime in the host's timezone.
+func ScheduleDueDate(localTime string, now time.Time, loc *time.Location) (string, bool) {
+	hour, minute, err := ParseScheduleLocalTime(localTime)
+	if err != nil {
+		return "", false
+	}
+
+	localNow := now.In(loc)
+	// on days of daylight saving time transitions, a local time that does not
+	// exist is normalized by time.Date using the offset in effect before the
+	// transition (e.g. 02:30 becomes 01:30 EST in America/New_York).
+	dueAt := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), hour, minute, 0, 0, loc)
+	if localNow.Before(dueAt) || localNow.Sub(dueAt) >= ScriptScheduleRunWindow {
+		return "", false
+	}
+	return localNow.Format("2006-01-02"), true
+}
+
+// HostLocation returns the location of the IANA timezone name reported by a
+// host, or UTC if the host did not report a timezone or if it is unknown.
+func HostLocation(timezone *string) *time.Location {
+	if timezone == nil || *timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ScriptScheduleHost is a host targeted by a script schedule, with the
+// information required to decide if the scheduled script is due on the host.
+type ScriptScheduleHost struct {
+	ScriptScheduleID uint   `db:"script_schedule_id"`
+	ScriptID         uint   `db:"script_id"`
+	ScriptName       string `db:"script_name"`
+	ScriptContentID  uint   `db:"script_content_id"`
+	LocalTime        string `db:"local_time"`
+	HostID           uint   `db:"host_id"`
+	HostPlatform     string `db:"host_platform"`
+	// HostTimezone is the IANA name of the host's timezone, nil if the host
+	// did not report it.
+	HostTimezone *string `db:"host_timezone"`
+	// LastRunDate is the local date ("YYYY-MM-DD") on which the script was last
+	// queued on the host by the schedule, nil if it never was.
+	LastRunDate *string `db:"last_run_date"`
+}
+
+// SupportsScript returns true if the platform of the host can run the script,
+// that is, PowerShell scripts on Windows and shell scripts on unix-like
+// platforms.
+func (h *ScriptScheduleHost) SupportsScript() bool {
+	switch {
+	case h.HostPlatform == "windows":
+		return filepath.Ext(h.ScriptName) == ".ps1"
+	case IsUnixLike(h.HostPlatform):
+		return filepath.Ext(h.ScriptName) == ".sh"
+	default:
+		return false
+	}
+}
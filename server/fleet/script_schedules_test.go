@@ -0,0 +1,108 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleLocalTime(t *testing.T) {
+	for _, c := range []struct {
+		in     string
+		hour   int
+		minute int
+		err    bool
+	}{
+		{"02:00", 2, 0, false},
+		{"23:59", 23, 59, false},
+		{"00:00", 0, 0, false},
+		{"2:00", 0, 0, true},
+		{"24:00", 0, 0, true},
+		{"12:60", 0, 0, true},
+		{"02:00:00", 0, 0, true},
+		{"", 0, 0, true},
+	} {
+		hour, minute, err := ParseScheduleLocalTime(c.in)
+		if c.err {
+			require.Error(t, err, c.in)
+			continue
+		}
+		require.NoError(t, err, c.in)
+		require.Equal(t, c.hour, hour, c.in)
+		require.Equal(t, c.minute, minute, c.in)
+	}
+}
+
+func TestScheduleDueDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// 2024-05-02 06:10 UTC is 02:10 in New York and 15:10 in Tokyo
+	now := time.Date(2024, 5, 2, 6, 10, 0, 0, time.UTC)
+
+	date, due := ScheduleDueDate("02:00", now, newYork)
+	require.True(t, due)
+	require.Equal(t, "2024-05-02", date)
+
+	_, due = ScheduleDueDate("02:00", now, tokyo)
+	require.False(t, due)
+	_, due = ScheduleDueDate("02:00", now, time.UTC)
+	require.False(t, due)
+
+	// the local date is the host's date, not the UTC date
+	date, due = ScheduleDueDate("15:00", now, tokyo)
+	require.True(t, due)
+	require.Equal(t, "2024-05-02", date)
+	date, due = ScheduleDueDate("22:30", time.Date(2024, 5, 2, 2, 45, 0, 0, time.UTC), newYork)
+	require.True(t, due)
+	require.Equal(t, "2024-05-01", date)
+
+	// not due before the local time nor after the run window
+	_, due = ScheduleDueDate("02:15", now, newYork)
+	require.False(t, due)
+	_, due = ScheduleDueDate("01:10", now, newYork)
+	require.False(t, due)
+	_, due = ScheduleDueDate("01:11", now, newYork)
+	require.True(t, due)
+
+	// on the day of the switch to daylight saving time, 02:30 does not exist
+	// in New York and the script is due at 01:30 EST.
+	date, due = ScheduleDueDate("02:30", time.Date(2024, 3, 10, 6, 45, 0, 0, time.UTC), newYork) // 01:45 EST
+	require.True(t, due)
+	require.Equal(t, "2024-03-10", date)
+	_, due = ScheduleDueDate("02:30", time.Date(2024, 3, 10, 7, 45, 0, 0, time.UTC), newYork) // 03:45 EDT
+	require.False(t, due)
+
+	// invalid local time
+	_, due = ScheduleDueDate("nope", now, newYork)
+	require.False(t, due)
+}
+
+func TestHostLocation(t *testing.T) {
+	require.Equal(t, time.UTC, HostLocation(nil))
+	require.Equal(t, time.UTC, HostLocation(ptr.String("")))
+	require.Equal(t, time.UTC, HostLocation(ptr.String("Not/A_Timezone")))
+	require.Equal(t, "Europe/Paris", HostLocation(ptr.String("Europe/Paris")).String())
+}
+
+func TestScriptScheduleHostSupportsScript(t *testing.T) {
+	for _, c := range []struct {
+		platform string
+		script   string
+		want     bool
+	}{
+		{"darwin", "script.sh", true},
+		{"ubuntu", "script.sh", true},
+		{"windows", "script.sh", false},
+		{"windows", "script.ps1", true},
+		{"darwin", "script.ps1", false},
+		{"chrome", "script.sh", false},
+	} {
+		h := ScriptScheduleHost{HostPlatform: c.platform, ScriptName: c.script}
+		require.Equal(t, c.want, h.SupportsScript(), "%s %s", c.platform, c.script)
+	}
+}
@@ -28,6 +28,13 @@ type Script struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	// ScriptContentID is the ID of the script contents, which are stored separately from the Script.
 	ScriptContentID uint `json:"-" db:"script_content_id"`
+	// SharedWithAllTeams is true if the script is a "no team" script that a
+	// global admin shared with all teams, i.e. it is part of the library of
+	// every team (unless the team opted out of it).
+	SharedWithAllTeams bool `json:"shared_with_all_teams" db:"shared_with_all_teams"`
+	// OptedOut is only set for the shared scripts listed for a team, it is
+	// true if the team opted out of the script.
+	OptedOut *bool `json:"opted_out,omitempty" db:"opted_out"`
 }
 
 func (s Script) AuthzType() string {
@@ -385,6 +392,13 @@ type HostLockWipeStatus struct {
 
 	// Linux uses a script for Wipe
 	WipeScript *HostScriptResult
+
+	// all platforms use a script to isolate the host from the network and to
+	// remove that isolation (macOS hosts also receive an isolation profile if
+	// they are enrolled in Fleet's MDM, but the state is tracked by the
+	// script).
+	IsolateScript   *HostScriptResult
+	UnisolateScript *HostScriptResult
 }
 
 func (s *HostLockWipeStatus) IsPendingLock() bool {
@@ -434,6 +448,24 @@ func (s HostLockWipeStatus) IsUnlocked() bool {
 	return !s.IsLocked() && !s.IsWiped()
 }
 
+func (s HostLockWipeStatus) IsPendingIsolate() bool {
+	// pending isolation if script execution request is queued but no result yet
+	return s.IsolateScript != nil && s.IsolateScript.ExitCode == nil
+}
+
+func (s HostLockWipeStatus) IsPendingUnisolate() bool {
+	// pending release of the isolation if script execution request is queued
+	// but no result yet
+	return s.UnisolateScript != nil && s.UnisolateScript.ExitCode == nil
+}
+
+func (s HostLockWipeStatus) IsIsolated() bool {
+	// this state is regardless of pending isolate/unisolate (it reports whether
+	// the host is isolated *now*). Isolated if a script was sent and succeeded.
+	return s.IsolateScript != nil && s.IsolateScript.ExitCode != nil &&
+		*s.IsolateScript.ExitCode == 0
+}
+
 func (s HostLockWipeStatus) IsWiped() bool {
 	switch s.HostFleetPlatform {
 	case "linux":
@@ -0,0 +1,120 @@
+package fleet
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SecretVariablePrefix is the prefix of the variables that reference a secret
+// variable in scripts and configuration profiles. For example,
+// $FLEET_SECRET_API_KEY (or ${FLEET_SECRET_API_KEY}) references the secret
+// variable named API_KEY.
+const SecretVariablePrefix = "FLEET_SECRET_"
+
+var (
+	secretVariableNameRegexp      = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	secretVariableReferenceRegexp = regexp.MustCompile(`\$(?:\{` + SecretVariablePrefix + `([A-Za-z0-9_]+)\}|` + SecretVariablePrefix + `([A-Za-z0-9_]+))`)
+)
+
+// SecretVariable is a named value stored encrypted by Fleet that can be
+// referenced from scripts and configuration profiles. The value is never
+// returned by the API.
+type SecretVariable struct {
+	ID        uint      `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Value     string    `json:"-" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (s *SecretVariable) AuthzType() string {
+	return "secret_variable"
+}
+
+// SecretVariablePayload is the payload used to create or update a secret
+// variable.
+type SecretVariablePayload struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Validate checks that the name and value of the secret variable are valid.
+// The name must not include the FLEET_SECRET_ prefix.
+func (p SecretVariablePayload) Validate() error {
+	switch {
+	case p.Name == "":
+		return NewInvalidArgumentError("name", "secret variable name cannot be empty")
+	case len(p.Name) > 255:
+		return NewInvalidArgumentError("name", "secret variable name is too long")
+	case !secretVariableNameRegexp.MatchString(p.Name):
+		return NewInvalidArgumentError("name", "secret variable name can only contain letters, digits and underscores")
+	case strings.HasPrefix(p.Name, SecretVariablePrefix):
+		return NewInvalidArgumentError("name", "secret variable name must not include the "+SecretVariablePrefix+" prefix")
+	case p.Value == "":
+		return NewInvalidArgumentError("value", "secret variable value cannot be empty")
+	}
+	return nil
+}
+
+// ReferencedSecretVariables returns the sorted, unique names of the secret
+// variables referenced in the document.
+func ReferencedSecretVariables(document string) []string {
+	matches := secretVariableReferenceRegexp.FindAllStringSubmatch(document, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(matches))
+	var names []string
+	for _, m := range matches {
+		name := m[1]
+		if name == "" {
+			name = m[2]
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandSecretVariables replaces the references to secret variables in the
+// document with the values provided, keyed by name. If escape is not nil,
+// values are escaped with it before being inserted (e.g. to escape XML).
+// References to unknown secret variables are left as-is.
+func ExpandSecretVariables(document string, values map[string]string, escape func(string) string) string {
+	return secretVariableReferenceRegexp.ReplaceAllStringFunc(document, func(ref string) string {
+		name := strings.TrimPrefix(strings.Trim(ref, "${}"), SecretVariablePrefix)
+		v, ok := values[name]
+		if !ok {
+			return ref
+		}
+		if escape != nil {
+			v = escape(v)
+		}
+		return v
+	})
+}
+
+// RedactSecretVariables replaces any occurrence of the provided secret values
+// in s with a masked value.
+func RedactSecretVariables(s string, values []string) string {
+	// replace longer values first so that a value that contains another one
+	// is fully redacted.
+	sorted := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, v := range sorted {
+		s = strings.ReplaceAll(s, v, MaskedPassword)
+	}
+	return s
+}
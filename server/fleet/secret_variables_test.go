@@ -0,0 +1,61 @@
+package fleet
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretVariablePayloadValidate(t *testing.T) {
+	cases := []struct {
+		payload SecretVariablePayload
+		wantErr string
+	}{
+		{SecretVariablePayload{Name: "API_KEY", Value: "abc"}, ""},
+		{SecretVariablePayload{Name: "api_key_2", Value: "abc"}, ""},
+		{SecretVariablePayload{Name: "", Value: "abc"}, "name cannot be empty"},
+		{SecretVariablePayload{Name: strings.Repeat("A", 256), Value: "abc"}, "too long"},
+		{SecretVariablePayload{Name: "API-KEY", Value: "abc"}, "letters, digits and underscores"},
+		{SecretVariablePayload{Name: "FLEET_SECRET_API_KEY", Value: "abc"}, "must not include the FLEET_SECRET_ prefix"},
+		{SecretVariablePayload{Name: "API_KEY", Value: ""}, "value cannot be empty"},
+	}
+	for _, c := range cases {
+		t.Run(c.payload.Name, func(t *testing.T) {
+			err := c.payload.Validate()
+			if c.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestReferencedSecretVariables(t *testing.T) {
+	require.Nil(t, ReferencedSecretVariables("echo hello"))
+	require.Nil(t, ReferencedSecretVariables("echo $FLEET_VAR_X $FLEET_SECRET_"))
+	require.Equal(t, []string{"A", "B_2"}, ReferencedSecretVariables(`curl -H "$FLEET_SECRET_B_2" ${FLEET_SECRET_A}/$FLEET_SECRET_B_2`))
+}
+
+func TestExpandSecretVariables(t *testing.T) {
+	values := map[string]string{"TOKEN": "s3cr<e>t", "URL": "https://example.com"}
+
+	got := ExpandSecretVariables(`curl -H "X: $FLEET_SECRET_TOKEN" ${FLEET_SECRET_URL}/x $FLEET_SECRET_OTHER`, values, nil)
+	require.Equal(t, `curl -H "X: s3cr<e>t" https://example.com/x $FLEET_SECRET_OTHER`, got)
+
+	escape := func(s string) string {
+		var sb strings.Builder
+		require.NoError(t, xml.EscapeText(&sb, []byte(s)))
+		return sb.String()
+	}
+	got = ExpandSecretVariables(`<string>$FLEET_SECRET_TOKEN</string>`, values, escape)
+	require.Equal(t, `<string>s3cr&lt;e&gt;t</string>`, got)
+}
+
+func TestRedactSecretVariables(t *testing.T) {
+	require.Equal(t, "no secrets", RedactSecretVariables("no secrets", []string{"abc"}))
+	require.Equal(t, "token=******** again ********", RedactSecretVariables("token=abc again abc", []string{"abc", ""}))
+	require.Equal(t, "********", RedactSecretVariables("abcdef", []string{"abc", "abcdef"}))
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/schema"
 	"github.com/fleetdm/fleet/v4/server/version"
 	"github.com/fleetdm/fleet/v4/server/websocket"
 )
@@ -93,6 +94,10 @@ type Service interface {
 	// GetFleetDesktopSummary returns a summary of the host used by Fleet Desktop to operate.
 	GetFleetDesktopSummary(ctx context.Context) (DesktopSummary, error)
 
+	// GetDeviceBranding returns the branding of the end-user facing pages for
+	// the currently authenticated device.
+	GetDeviceBranding(ctx context.Context) (DesktopOrgInfo, error)
+
 	// SetEnterpriseOverrides allows the enterprise service to override specific methods
 	// that can't be easily overridden via embedding.
 	//
@@ -199,6 +204,152 @@ type Service interface {
 	GetSessionByKey(ctx context.Context, key string) (session *Session, err error)
 	DeleteSession(ctx context.Context, id uint) (err error)
 
+	// /////////////////////////////////////////////////////////////////////////////
+	// Multi-factor authentication
+
+	// LoginMFA completes a password login that returned an MFARequiredError
+	// with the second factor of the user.
+	LoginMFA(ctx context.Context, payload MFALoginPayload) (user *User, session *Session, err error)
+	// MFAEnrollmentRequired returns true if the organization requires MFA and
+	// the user has not enrolled a second factor yet. It skips authorization
+	// checks and should only be used by the authentication middleware.
+	MFAEnrollmentRequired(ctx context.Context, user *User) (bool, error)
+	// GetMFAStatus returns the second factors configuration of the current
+	// user.
+	GetMFAStatus(ctx context.Context) (*UserMFAStatus, error)
+	// BeginTOTPEnrollment generates a new TOTP secret for the current user. It
+	// must be confirmed with ConfirmTOTPEnrollment before it can be used.
+	BeginTOTPEnrollment(ctx context.Context) (*TOTPEnrollment, error)
+	// ConfirmTOTPEnrollment confirms the pending TOTP secret of the current
+	// user with a valid code. If this is the first second factor of the user,
+	// new recovery codes are returned.
+	ConfirmTOTPEnrollment(ctx context.Context, code string) (recoveryCodes []string, err error)
+	// DisableTOTP removes the TOTP secret of the current user.
+	DisableTOTP(ctx context.Context) error
+	// BeginWebAuthnRegistration starts the registration of a new WebAuthn
+	// credential for the current user.
+	BeginWebAuthnRegistration(ctx context.Context) (*WebAuthnRegistrationOptions, error)
+	// FinishWebAuthnRegistration verifies and stores the new WebAuthn
+	// credential of the current user. If this is the first second factor of
+	// the user, new recovery codes are returned.
+	FinishWebAuthnRegistration(ctx context.Context, payload WebAuthnRegistrationPayload) (cred *UserWebAuthnCredential, recoveryCodes []string, err error)
+	// DeleteWebAuthnCredential removes a WebAuthn credential of the current
+	// user.
+	DeleteWebAuthnCredential(ctx context.Context, id uint) error
+	// RegenerateMFARecoveryCodes replaces the recovery codes of the current
+	// user.
+	RegenerateMFARecoveryCodes(ctx context.Context) ([]string, error)
+	// ResetUserMFA removes all second factors of the user, e.g. after they
+	// lost access to them.
+	ResetUserMFA(ctx context.Context, userID uint) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// API tokens
+
+	// NewAPIToken creates a new team-scoped API token. The secret is only
+	// returned by this call.
+	NewAPIToken(ctx context.Context, payload APITokenPayload) (*APIToken, error)
+	// ListAPITokens lists the API tokens of the provided team, or of all teams
+	// if teamID is nil.
+	ListAPITokens(ctx context.Context, teamID *uint) ([]*APIToken, error)
+	// DeleteAPIToken revokes the API token identified by id.
+	DeleteAPIToken(ctx context.Context, id uint) error
+	// AuthenticateAPIToken returns the API token matching the provided secret
+	// along with the user that represents it for authorization purposes.
+	AuthenticateAPIToken(ctx context.Context, token string) (*APIToken, *User, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Tenants
+
+	// NewTenant creates a new tenant.
+	NewTenant(ctx context.Context, payload TenantPayload) (*Tenant, error)
+	// ListTenants lists the tenants.
+	ListTenants(ctx context.Context) ([]*Tenant, error)
+	// GetTenant returns the tenant identified by id.
+	GetTenant(ctx context.Context, id uint) (*Tenant, error)
+	// ModifyTenant modifies the name, description and config of the tenant.
+	ModifyTenant(ctx context.Context, id uint, payload TenantPayload) (*Tenant, error)
+	// DeleteTenant deletes the tenant, which must not have teams nor users.
+	DeleteTenant(ctx context.Context, id uint) error
+	// AddTenantTeams moves the teams to the tenant.
+	AddTenantTeams(ctx context.Context, id uint, teamIDs []uint) (*Tenant, error)
+	// DeleteTenantTeams moves the teams of the tenant out of any tenant.
+	DeleteTenantTeams(ctx context.Context, id uint, teamIDs []uint) (*Tenant, error)
+	// AddTenantUsers moves the users to the tenant.
+	AddTenantUsers(ctx context.Context, id uint, userIDs []uint) (*Tenant, error)
+	// DeleteTenantUsers moves the users of the tenant out of any tenant.
+	DeleteTenantUsers(ctx context.Context, id uint, userIDs []uint) (*Tenant, error)
+	// SetTenantAPNsCertificate sets the APNs certificate and key used for the
+	// hosts of the tenant.
+	SetTenantAPNsCertificate(ctx context.Context, id uint, payload TenantAPNsPayload) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Secret variables
+
+	// CreateSecretVariable creates a new secret variable. The value is never
+	// returned by the API.
+	CreateSecretVariable(ctx context.Context, payload SecretVariablePayload) (*SecretVariable, error)
+	// ListSecretVariables lists the secret variables, without their values.
+	ListSecretVariables(ctx context.Context) ([]*SecretVariable, error)
+	// DeleteSecretVariable deletes the secret variable identified by id.
+	DeleteSecretVariable(ctx context.Context, id uint) error
+	// ApplySecretVariablesSpec creates the provided secret variables, or
+	// updates the value of those that already exist. It is used by fleetctl
+	// gitops.
+	ApplySecretVariablesSpec(ctx context.Context, secrets []SecretVariablePayload, dryRun bool) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host notes and custom fields
+
+	// UpdateHostCustomFields updates the notes and custom field values of the
+	// host and returns the updated values.
+	UpdateHostCustomFields(ctx context.Context, hostID uint, payload HostCustomFieldsPayload) (*HostCustomFields, error)
+	// ApplyHostCustomFieldsSpec updates the notes and custom field values of
+	// the hosts of the spec, it is used by fleetctl apply.
+	ApplyHostCustomFieldsSpec(ctx context.Context, spec HostCustomFieldsSpec) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host groups
+
+	// NewHostGroup creates a new host group, optionally with the selected
+	// hosts as initial members.
+	NewHostGroup(ctx context.Context, payload HostGroupPayload, hosts HostGroupHostsPayload) (*HostGroup, error)
+	// GetHostGroup returns the host group identified by id.
+	GetHostGroup(ctx context.Context, id uint) (*HostGroup, error)
+	// ListHostGroups lists the host groups.
+	ListHostGroups(ctx context.Context, opt ListOptions) ([]*HostGroup, error)
+	// ModifyHostGroup updates the name and description of the host group.
+	ModifyHostGroup(ctx context.Context, id uint, payload HostGroupPayload) (*HostGroup, error)
+	// DeleteHostGroup deletes the host group identified by id.
+	DeleteHostGroup(ctx context.Context, id uint) error
+	// AddHostsToHostGroup adds the selected hosts to the host group.
+	AddHostsToHostGroup(ctx context.Context, id uint, hosts HostGroupHostsPayload) (*HostGroup, error)
+	// RemoveHostsFromHostGroup removes the selected hosts from the host group.
+	RemoveHostsFromHostGroup(ctx context.Context, id uint, hosts HostGroupHostsPayload) (*HostGroup, error)
+	// RunHostGroupScript queues the saved script on all hosts of the host group
+	// that can run it.
+	RunHostGroupScript(ctx context.Context, id uint, scriptID uint) (*HostGroupScriptRun, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Enrollment approval
+
+	// ListHostsPendingApproval lists the hosts that are pending enrollment
+	// approval.
+	ListHostsPendingApproval(ctx context.Context) ([]*HostPendingApproval, error)
+	// ApproveHostEnrollment approves the enrollment of a host that is pending
+	// approval.
+	ApproveHostEnrollment(ctx context.Context, hostID uint) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Duplicate hosts
+
+	// ListDuplicateHosts lists the sets of hosts that have the same hardware
+	// serial number or hardware UUID.
+	ListDuplicateHosts(ctx context.Context) ([]*DuplicateHostSet, error)
+	// MergeHosts merges the duplicate hosts into the target host and deletes
+	// them.
+	MergeHosts(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// PackService is the service interface for managing query packs.
 
@@ -286,6 +437,12 @@ type Service interface {
 	// DeleteQueries deletes the existing query objects with the provided IDs. The number of deleted queries is returned
 	// along with any error.
 	DeleteQueries(ctx context.Context, ids []uint) (uint, error)
+	// ValidateQuery validates the SQL of a query against the schema of the
+	// osquery and fleetd tables for the platforms it targets, without saving it.
+	ValidateQuery(ctx context.Context, p QueryValidationPayload) (*QueryValidationResult, error)
+	// ListOsquerySchemaTables returns the tables of the osquery schema catalog,
+	// i.e. the osquery tables and the extension tables of fleetd.
+	ListOsquerySchemaTables(ctx context.Context, opts OsquerySchemaOptions) ([]schema.Table, error)
 
 	// /////////////////////////////////////////////////////////////////////////////
 	// CampaignService defines the distributed query campaign related service methods
@@ -293,19 +450,21 @@ type Service interface {
 	// NewDistributedQueryCampaignByNames creates a new distributed query campaign with the provided query (or the query
 	// referenced by ID) and host/label targets (specified by name).
 	NewDistributedQueryCampaignByNames(
-		ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string,
+		ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, postProcessing *QueryPostProcessing,
 	) (*DistributedQueryCampaign, error)
 
 	// NewDistributedQueryCampaign creates a new distributed query campaign with the provided query (or the query
-	// referenced by ID) and host/label targets
+	// referenced by ID) and host/label targets. If postProcessing is not nil, an aggregate of the results is
+	// streamed instead of the raw rows.
 	NewDistributedQueryCampaign(
-		ctx context.Context, queryString string, queryID *uint, targets HostTargets,
+		ctx context.Context, queryString string, queryID *uint, targets HostTargets, postProcessing *QueryPostProcessing,
 	) (*DistributedQueryCampaign, error)
 
 	// StreamCampaignResults streams updates with query results and expected host totals over the provided websocket.
 	// Note that the type signature is somewhat inconsistent due to this being a streaming API and not the typical
-	// go-kit RPC style.
-	StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint)
+	// go-kit RPC style. With version 2 of the streaming protocol, the campaign can
+	// be resumed from the provided cursor when the client reconnects.
+	StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint, opts LiveQueryStreamOptions)
 
 	GetCampaignReader(ctx context.Context, campaign *DistributedQueryCampaign) (<-chan interface{}, context.CancelFunc, error)
 	CompleteCampaign(ctx context.Context, campaign *DistributedQueryCampaign) error
@@ -411,6 +570,22 @@ type Service interface {
 	// OSVersion returns an operating system and associated host counts
 	OSVersion(ctx context.Context, osVersionID uint, teamID *uint, includeCVSS bool) (*OSVersion, *time.Time, error)
 
+	// ListCertificateInventory returns the certificates found on the hosts
+	// (optionally of a team), aggregated across hosts.
+	ListCertificateInventory(ctx context.Context, opts CertificateInventoryListOptions) ([]*CertificateInventoryItem, int, *PaginationMetadata, error)
+
+	// ListHostCertificates returns the certificates found on a host, as
+	// reported by osquery and by MDM.
+	ListHostCertificates(ctx context.Context, hostID uint) ([]*HostCertificate, error)
+
+	// ListHostEnrollmentCertificates returns the TLS client certificates that
+	// the host used to enroll instead of an enroll secret.
+	ListHostEnrollmentCertificates(ctx context.Context, hostID uint) ([]*HostEnrollmentCertificate, error)
+	// RevokeHostEnrollmentCertificates revokes the enrollment certificates of
+	// the host and forces it to enroll again. It returns the number of
+	// certificates revoked.
+	RevokeHostEnrollmentCertificates(ctx context.Context, hostID uint) (int, error)
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// AppConfigService provides methods for configuring  the Fleet application
 
@@ -419,6 +594,53 @@ type Service interface {
 	AppConfigObfuscated(ctx context.Context) (info *AppConfig, err error)
 	ModifyAppConfig(ctx context.Context, p []byte, applyOpts ApplySpecOptions) (info *AppConfig, err error)
 	SandboxEnabled() bool
+	// VerifyGitOpsModeAllowed verifies that the authenticated user is allowed
+	// to change the configuration, which is not the case of the interactive
+	// users when GitOps mode is enabled. If an error is returned, authorization
+	// is skipped so the error can be raised to the user.
+	VerifyGitOpsModeAllowed(ctx context.Context) error
+
+	// ListConfigVersions lists the recorded versions of the global
+	// configuration (if teamID is nil) or team configuration, most recent first.
+	ListConfigVersions(ctx context.Context, teamID *uint, opts ListOptions) ([]*ConfigVersion, *PaginationMetadata, error)
+	// GetConfigVersion returns the configuration version identified by id, with
+	// obfuscated credentials.
+	GetConfigVersion(ctx context.Context, id uint) (*ConfigVersion, error)
+	// DiffConfigVersions returns the changes from the configuration version
+	// fromID to the version toID, which must be versions of the same
+	// configuration.
+	DiffConfigVersions(ctx context.Context, fromID, toID uint) (*ConfigVersionDiff, error)
+	// RollbackConfigVersion restores the configuration and configuration
+	// profiles recorded in the version identified by id, and returns the new
+	// version recorded for the restored configuration.
+	RollbackConfigVersion(ctx context.Context, id uint) (*ConfigVersion, error)
+
+	// ListEmailTemplates returns the templates of all the emails that can be
+	// customized, with the defaults filled in for the emails that are not.
+	ListEmailTemplates(ctx context.Context) ([]*EmailTemplate, error)
+	// GetEmailTemplate returns the template of the email name, with the
+	// defaults filled in if it is not customized.
+	GetEmailTemplate(ctx context.Context, name EmailTemplateName) (*EmailTemplate, error)
+	// ModifyEmailTemplate customizes the template of the email name.
+	ModifyEmailTemplate(ctx context.Context, name EmailTemplateName, payload EmailTemplatePayload) (*EmailTemplate, error)
+	// ResetEmailTemplate removes the customization of the email name.
+	ResetEmailTemplate(ctx context.Context, name EmailTemplateName) error
+	// PreviewEmailTemplate renders the template of the email name with sample
+	// data, with the changes of the payload applied but not saved.
+	PreviewEmailTemplate(ctx context.Context, name EmailTemplateName, payload EmailTemplatePayload) (*EmailTemplatePreview, error)
+
+	// ListTranslations returns the translations of the end-user facing
+	// messages for all the locales.
+	ListTranslations(ctx context.Context) ([]*Translations, error)
+	// GetTranslations returns the translations of the end-user facing messages
+	// for the locale.
+	GetTranslations(ctx context.Context, locale string) (*Translations, error)
+	// ApplyTranslations creates or replaces the translations of the end-user
+	// facing messages for the locale.
+	ApplyTranslations(ctx context.Context, locale string, messages map[MessageKey]string) (*Translations, error)
+	// DeleteTranslations deletes the translations of the end-user facing
+	// messages for the locale.
+	DeleteTranslations(ctx context.Context, locale string) error
 
 	// ApplyEnrollSecretSpec adds and updates the enroll secrets specified in the spec.
 	ApplyEnrollSecretSpec(ctx context.Context, spec *EnrollSecretSpec) error
@@ -568,6 +790,12 @@ type Service interface {
 	// ListHostPastActivities lists the activities that have already happened for the specified host.
 	ListHostPastActivities(ctx context.Context, hostID uint, opt ListOptions) ([]*Activity, *PaginationMetadata, error)
 
+	// ListChanges returns the host updates and new activities that happened
+	// after the cursor of the options, in the order they must be streamed, and
+	// the cursor to resume the stream from. If the options have no cursor, no
+	// change is returned and the cursor is the current position of the stream.
+	ListChanges(ctx context.Context, opts ChangeStreamOptions) ([]ChangeEvent, ChangeStreamCursor, error)
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// UserRolesService
 
@@ -608,6 +836,15 @@ type Service interface {
 	ApplyPolicySpecs(ctx context.Context, policies []*PolicySpec) error
 	CountGlobalPolicies(ctx context.Context, matchQuery string) (int, error)
 
+	// DryRunPolicyAutomation simulates the failing policies automation for the
+	// policy, returning the hosts that would trigger it and the webhook requests
+	// or tickets that would be created, without sending or creating them.
+	DryRunPolicyAutomation(ctx context.Context, policyID uint, payload PolicyAutomationDryRunPayload) (*PolicyAutomationDryRunResult, error)
+
+	// GetPolicyComplianceTrends returns the daily number of passing and failing
+	// hosts for the policies over a range of days.
+	GetPolicyComplianceTrends(ctx context.Context, opts PolicyComplianceTrendsOptions) (*PolicyComplianceTrends, error)
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// Software
 
@@ -630,11 +867,47 @@ type Service interface {
 	Vulnerability(ctx context.Context, cve string, teamID *uint, useCVSScores bool) (*VulnerabilityWithMetadata, error)
 	// CountVulnerabilities returns the number of vulnerabilities based on the provided options.
 	CountVulnerabilities(ctx context.Context, opt VulnListOptions) (uint, error)
+	// GetVulnerabilitySLAReport returns the vulnerabilities that exceeded the
+	// remediation SLAs on the hosts of the team (nil for the hosts with no team).
+	GetVulnerabilitySLAReport(ctx context.Context, teamID *uint) (*VulnerabilitySLAReport, error)
 	// ListOSVersionsByCVE returns a list of OS versions affected by the provided CVE.
 	ListOSVersionsByCVE(ctx context.Context, cve string, teamID *uint) (result []*VulnerableOS, updatedAt time.Time, err error)
 	// ListSoftwareByCVE returns a list of software affected by the provided CVE.
 	ListSoftwareByCVE(ctx context.Context, cve string, teamID *uint) (result []*VulnerableSoftware, updatedAt time.Time, err error)
 
+	// /////////////////////////////////////////////////////////////////////////////
+	// Data exports
+
+	// ExportHosts exports the hosts matching the options. If there are at most
+	// DataExportMaxSyncRows hosts, the export is generated immediately and
+	// returned with its Data set, otherwise it is generated in the background
+	// and returned with a pending status.
+	ExportHosts(ctx context.Context, opts HostExportOptions, format DataExportFormat) (*DataExport, error)
+	// ExportSoftware exports the software matching the options, see
+	// ExportHosts.
+	ExportSoftware(ctx context.Context, opts SoftwareListOptions, format DataExportFormat) (*DataExport, error)
+	// ExportVulnerabilities exports the vulnerabilities matching the options,
+	// see ExportHosts.
+	ExportVulnerabilities(ctx context.Context, opts VulnListOptions, format DataExportFormat) (*DataExport, error)
+	// GetDataExport returns the export generated in the background.
+	GetDataExport(ctx context.Context, id uint) (*DataExport, error)
+	// DownloadDataExport returns the completed export with its Data set.
+	DownloadDataExport(ctx context.Context, id uint) (*DataExport, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Admin notifications
+
+	// ListAdminNotifications returns the admin notifications with their read
+	// state for the current user, and the number of notifications the user
+	// did not read.
+	ListAdminNotifications(ctx context.Context, opts AdminNotificationListOptions) (notifications []*AdminNotification, meta *PaginationMetadata, unreadCount int, err error)
+	// MarkAdminNotificationsRead marks the admin notifications as read for the
+	// current user. If ids is empty, all the notifications are marked as read.
+	MarkAdminNotificationsRead(ctx context.Context, ids []uint) error
+	// DeleteAdminNotification dismisses the admin notification for all the
+	// admins.
+	DeleteAdminNotification(ctx context.Context, id uint) error
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// Team Policies
 
@@ -667,7 +940,7 @@ type Service interface {
 	GetHostDEPAssignment(ctx context.Context, host *Host) (*HostDEPAssignment, error)
 
 	// NewMDMAppleConfigProfile creates a new configuration profile for the specified team.
-	NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r io.Reader, labels []string) (*MDMAppleConfigProfile, error)
+	NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r io.Reader, labels MDMProfileLabelTargeting, rollout *MDMProfileRolloutSettings) (*MDMAppleConfigProfile, error)
 	// NewMDMAppleConfigProfileWithPayload creates a new declaration for the specified team.
 	NewMDMAppleDeclaration(ctx context.Context, teamID uint, r io.Reader, labels []string, name string) (*MDMAppleDeclaration, error)
 
@@ -776,6 +1049,25 @@ type Service interface {
 	// MMDAppleEraseDevice erases a host
 	MDMAppleEraseDevice(ctx context.Context, hostID uint) error
 
+	// MDMAppleRestartDevice sends the RestartDevice MDM command to a macOS, iOS
+	// or iPadOS host, and returns the UUID of the command.
+	MDMAppleRestartDevice(ctx context.Context, hostID uint) (string, error)
+
+	// MDMAppleShutDownDevice sends the ShutDownDevice MDM command to a macOS,
+	// iOS or iPadOS host, and returns the UUID of the command.
+	MDMAppleShutDownDevice(ctx context.Context, hostID uint) (string, error)
+
+	// MDMAppleUnlockUserAccount sends the UnlockUserAccount MDM command to a
+	// macOS host to unlock the local user account username, and returns the
+	// UUID of the command.
+	MDMAppleUnlockUserAccount(ctx context.Context, hostID uint, username string) (string, error)
+
+	// MDMAppleRepairHost repairs the MDM enrollment of an Apple host: it
+	// renews its SCEP identity certificate, installs fleetd again (macOS
+	// only), sends its configuration profiles again and requests its device
+	// information.
+	MDMAppleRepairHost(ctx context.Context, hostID uint) (*MDMAppleHostRepair, error)
+
 	// MDMListHostConfigurationProfiles returns configuration profiles for a given host
 	MDMListHostConfigurationProfiles(ctx context.Context, hostID uint) ([]*MDMAppleConfigProfile, error)
 
@@ -817,6 +1109,16 @@ type Service interface {
 
 	GetMDMAppleBootstrapPackageSummary(ctx context.Context, teamID *uint) (*MDMAppleBootstrapPackageSummary, error)
 
+	// ExportMDMAssets returns a bundle of the MDM assets of the server (APNs,
+	// SCEP and ABM certificates, keys and tokens, the tenants' MDM assets and
+	// the bootstrap packages), encrypted with the passphrase.
+	ExportMDMAssets(ctx context.Context, passphrase string) ([]byte, error)
+	// ImportMDMAssets restores the tenants' MDM assets and the bootstrap
+	// packages of a bundle exported by ExportMDMAssets. Existing assets and
+	// packages are replaced only if overwrite is true. The assets set in the
+	// server configuration can't be imported and are skipped.
+	ImportMDMAssets(ctx context.Context, bundle []byte, passphrase string, overwrite bool) (*MDMAssetsImportResult, error)
+
 	// MDMGetEULABytes returns the contents of the EULA that matches
 	// the given token.
 	//
@@ -858,6 +1160,16 @@ type Service interface {
 
 	// TriggerCronSchedule attempts to trigger an ad-hoc run of the named cron schedule.
 	TriggerCronSchedule(ctx context.Context, name string) error
+	// ListCronSchedules returns the status of the cron schedules of the
+	// Fleet server, i.e. their current run, last run and next scheduled run.
+	ListCronSchedules(ctx context.Context) ([]*CronScheduleStatus, error)
+	// PauseCronSchedule pauses or resumes the scheduled runs of the named
+	// cron schedule on all the Fleet instances.
+	PauseCronSchedule(ctx context.Context, name string, paused bool) error
+
+	// GetUsageAnalytics returns the local dashboard of the detailed usage
+	// analytics for the last days.
+	GetUsageAnalytics(ctx context.Context, days int) (*UsageAnalyticsDashboard, error)
 
 	// ResetAutomation sets the policies and all policies of the listed teams to fire again
 	// for all hosts that are already marked as failing.
@@ -906,6 +1218,14 @@ type Service interface {
 	// Set or update the disk encryption key for a host.
 	SetOrUpdateDiskEncryptionKey(ctx context.Context, encryptionKey, clientError string) error
 
+	// EscrowLinuxDiskEncryptionKey stores the LUKS passphrase of a Linux host,
+	// or the error that prevented the host from escrowing it.
+	EscrowLinuxDiskEncryptionKey(ctx context.Context, passphrase string, keySlot *uint, clientError string) error
+
+	// SetLinuxDiskEncryptionEnforcementStatus stores the state of the
+	// enforcement of disk encryption reported by a Linux host.
+	SetLinuxDiskEncryptionEnforcementStatus(ctx context.Context, payload OrbitLinuxDiskEncryptionEnforcementPayload) error
+
 	// GetMDMWindowsConfigProfile retrieves the specified configuration profile.
 	GetMDMWindowsConfigProfile(ctx context.Context, profileUUID string) (*MDMWindowsConfigProfile, error)
 
@@ -918,15 +1238,25 @@ type Service interface {
 
 	// NewMDMWindowsConfigProfile creates a new Windows configuration profile for
 	// the specified team.
-	NewMDMWindowsConfigProfile(ctx context.Context, teamID uint, profileName string, r io.Reader, labels []string) (*MDMWindowsConfigProfile, error)
+	NewMDMWindowsConfigProfile(ctx context.Context, teamID uint, profileName string, r io.Reader, labels MDMProfileLabelTargeting, rollout *MDMProfileRolloutSettings) (*MDMWindowsConfigProfile, error)
 
 	// NewMDMUnsupportedConfigProfile is called when a profile with an
 	// unsupported extension is uploaded.
 	NewMDMUnsupportedConfigProfile(ctx context.Context, teamID uint, filename string) error
 
+	// NewMDMUnsupportedDeclarationFields is called when a declaration is
+	// uploaded with fields that are only supported for configuration profiles
+	// (labels_include_any, labels_exclude_any or the rollout settings).
+	NewMDMUnsupportedDeclarationFields(ctx context.Context, teamID uint, fields string) error
+
 	// ListMDMConfigProfiles returns a list of paginated configuration profiles.
 	ListMDMConfigProfiles(ctx context.Context, teamID *uint, opt ListOptions) ([]*MDMConfigProfilePayload, *PaginationMetadata, error)
 
+	// PreviewMDMProfileTargets returns the hosts of the team (or no team if
+	// teamID is nil) and platform that would be targeted by a configuration
+	// profile with the provided labels.
+	PreviewMDMProfileTargets(ctx context.Context, teamID *uint, platform string, labels MDMProfileLabelTargeting) (*MDMProfileTargetsPreview, error)
+
 	// BatchSetMDMProfiles replaces the custom Windows/macOS profiles for a specified
 	// team or for hosts with no team.
 	BatchSetMDMProfiles(
@@ -945,6 +1275,30 @@ type Service interface {
 	// ResendHostMDMProfile resends the MDM profile to the host.
 	ResendHostMDMProfile(ctx context.Context, hostID uint, profileUUID string) error
 
+	// NewMDMProfileRedeliveryCampaign creates a campaign that redelivers the
+	// configuration profile to its hosts at the requested rate.
+	NewMDMProfileRedeliveryCampaign(ctx context.Context, payload MDMProfileRedeliveryCampaignPayload) (*MDMProfileRedeliveryCampaign, error)
+
+	// ListMDMProfileRedeliveryCampaigns lists the profile redelivery campaigns
+	// of the team (nil for "No team").
+	ListMDMProfileRedeliveryCampaigns(ctx context.Context, opts MDMProfileRedeliveryCampaignListOptions) ([]*MDMProfileRedeliveryCampaign, error)
+
+	// GetMDMProfileRedeliveryCampaign returns the profile redelivery campaign,
+	// including its progress.
+	GetMDMProfileRedeliveryCampaign(ctx context.Context, id uint) (*MDMProfileRedeliveryCampaign, error)
+
+	// ModifyMDMProfileRedeliveryCampaign pauses or resumes the profile
+	// redelivery campaign, or changes its rate.
+	ModifyMDMProfileRedeliveryCampaign(ctx context.Context, id uint, payload MDMProfileRedeliveryCampaignUpdate) (*MDMProfileRedeliveryCampaign, error)
+
+	// GetMDMProfileRollout returns the staged rollout of the configuration
+	// profile, along with the verification results of its canary hosts.
+	GetMDMProfileRollout(ctx context.Context, profileUUID string) (*MDMProfileRollout, error)
+
+	// ModifyMDMProfileRollout continues, halts or restarts the staged rollout
+	// of the configuration profile.
+	ModifyMDMProfileRollout(ctx context.Context, profileUUID string, payload MDMProfileRolloutUpdate) (*MDMProfileRollout, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// Host Script Execution
 
@@ -988,8 +1342,165 @@ type Service interface {
 	// hosts with no team.
 	BatchSetScripts(ctx context.Context, maybeTmID *uint, maybeTmName *string, payloads []ScriptPayload, dryRun bool) error
 
+	// NewScriptSchedule schedules a saved script to run every day at localTime
+	// ("HH:MM") in the local timezone of each host of the script's team.
+	NewScriptSchedule(ctx context.Context, scriptID uint, localTime string) (*ScriptSchedule, error)
+
+	// ListScriptSchedules returns the schedules of the scripts of a team (or
+	// no team if teamID is nil).
+	ListScriptSchedules(ctx context.Context, teamID *uint) ([]*ScriptSchedule, error)
+
+	// DeleteScriptSchedule deletes a script schedule.
+	DeleteScriptSchedule(ctx context.Context, id uint) error
+
+	// UpdateScriptSharing shares a "no team" script with all teams, or stops
+	// sharing it.
+	UpdateScriptSharing(ctx context.Context, scriptID uint, payload SharedLibraryItemUpdate) (*Script, error)
+
+	// UpdateMDMConfigProfileSharing shares a "no team" Apple or Windows
+	// configuration profile with all teams, or stops sharing it.
+	UpdateMDMConfigProfileSharing(ctx context.Context, profileUUID string, payload SharedLibraryItemUpdate) error
+
+	// GetTeamSharedLibraryOptOuts returns the shared scripts and configuration
+	// profiles that the team opted out of.
+	GetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint) (*TeamSharedLibraryOptOuts, error)
+
+	// SetTeamSharedLibraryOptOuts replaces the shared scripts and configuration
+	// profiles that the team opted out of.
+	SetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint, optOuts TeamSharedLibraryOptOuts) (*TeamSharedLibraryOptOuts, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host Log Collection
+
+	// RequestHostLogCollection requests the collection of logs from a host.
+	// The logs are collected and uploaded asynchronously by orbit.
+	RequestHostLogCollection(ctx context.Context, payload *HostLogCollectionPayload) (*HostLogCollection, error)
+	// ListHostLogCollections returns the log collection requests of a host.
+	ListHostLogCollections(ctx context.Context, hostID uint) ([]*HostLogCollection, error)
+	// GetHostLogCollection returns the log collection request identified by
+	// the execution id, and the collected logs if downloadRequested is true.
+	GetHostLogCollection(ctx context.Context, execID string, downloadRequested bool) (*HostLogCollection, []byte, error)
+	// GetHostLogCollectionRequest returns the log collection request to the
+	// orbit host it is for.
+	GetHostLogCollectionRequest(ctx context.Context, execID string) (*HostLogCollection, error)
+	// SaveHostLogCollectionResult saves the logs collected and uploaded by an
+	// orbit host.
+	SaveHostLogCollectionResult(ctx context.Context, result *HostLogCollectionResultPayload) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host File Retrieval
+
+	// RequestHostFileRetrieval requests the retrieval of a file from a host.
+	// The path must be allowed by the file retrieval settings of the host's
+	// team. The file is read and uploaded asynchronously by orbit.
+	RequestHostFileRetrieval(ctx context.Context, payload *HostFileRetrievalPayload) (*HostFileRetrieval, error)
+	// ListHostFileRetrievals returns the file retrieval requests of a host.
+	ListHostFileRetrievals(ctx context.Context, hostID uint) ([]*HostFileRetrieval, error)
+	// GetHostFileRetrieval returns the file retrieval request identified by
+	// the execution id, and the file contents if downloadRequested is true.
+	GetHostFileRetrieval(ctx context.Context, execID string, downloadRequested bool) (*HostFileRetrieval, []byte, error)
+	// GetHostFileRetrievalRequest returns the file retrieval request to the
+	// orbit host it is for.
+	GetHostFileRetrievalRequest(ctx context.Context, execID string) (*HostFileRetrieval, error)
+	// SaveHostFileRetrievalResult saves the file uploaded by an orbit host.
+	SaveHostFileRetrievalResult(ctx context.Context, result *HostFileRetrievalResultPayload) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host Pprof Capture
+
+	// RequestHostPprofCapture requests the capture of a pprof profile of the
+	// fleetd agent running on a host. The profile is captured and uploaded
+	// asynchronously by orbit.
+	RequestHostPprofCapture(ctx context.Context, payload *HostPprofCapturePayload) (*HostPprofCapture, error)
+	// ListHostPprofCaptures returns the pprof capture requests of a host.
+	ListHostPprofCaptures(ctx context.Context, hostID uint) ([]*HostPprofCapture, error)
+	// GetHostPprofCapture returns the pprof capture request identified by the
+	// execution id, and the profile if downloadRequested is true.
+	GetHostPprofCapture(ctx context.Context, execID string, downloadRequested bool) (*HostPprofCapture, []byte, error)
+	// GetHostPprofCaptureRequest returns the pprof capture request to the orbit
+	// host it is for.
+	GetHostPprofCaptureRequest(ctx context.Context, execID string) (*HostPprofCapture, error)
+	// SaveHostPprofCaptureResult saves the profile uploaded by an orbit host.
+	SaveHostPprofCaptureResult(ctx context.Context, result *HostPprofCaptureResultPayload) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Host Remote Query
+
+	// RequestHostRemoteQuery requests a one-off osquery query to be run on a
+	// host by the fleetd agent, outside of the distributed queries interval.
+	RequestHostRemoteQuery(ctx context.Context, payload *HostRemoteQueryPayload) (*HostRemoteQuery, error)
+	// ListHostRemoteQueries returns the remote query requests of a host.
+	ListHostRemoteQueries(ctx context.Context, hostID uint) ([]*HostRemoteQuery, error)
+	// GetHostRemoteQuery returns the remote query request identified by the
+	// execution id, with its results.
+	GetHostRemoteQuery(ctx context.Context, execID string) (*HostRemoteQuery, error)
+	// GetHostRemoteQueryRequest returns the remote query request to the orbit
+	// host it is for.
+	GetHostRemoteQueryRequest(ctx context.Context, execID string) (*HostRemoteQuery, error)
+	// SaveHostRemoteQueryResult saves the results sent by an orbit host.
+	SaveHostRemoteQueryResult(ctx context.Context, result *HostRemoteQueryResultPayload) error
+
+	// SaveHostTamperEvent records a tamper event reported by an orbit host as
+	// a host activity and sends it to the tamper events webhook, if enabled.
+	SaveHostTamperEvent(ctx context.Context, event *HostTamperEventPayload) error
+
+	// SaveHostServiceRecovery records that the recovery actions of the fleetd
+	// service fired on an orbit host as a host activity.
+	SaveHostServiceRecovery(ctx context.Context, recovery *HostServiceRecoveryPayload) error
+
 	// Script-based methods (at least for some platforms, MDM-based for others)
-	LockHost(ctx context.Context, hostID uint) error
+
+	// LockHost requests the host to be locked. The confirmation must be the
+	// host's serial number or its lock/wipe confirmation token. If the host's
+	// team (or no team) requires a second approver, the first request is
+	// recorded and pendingApproval is true, the host is locked when a
+	// different user sends the same request.
+	LockHost(ctx context.Context, hostID uint, confirmation string) (pendingApproval bool, err error)
 	UnlockHost(ctx context.Context, hostID uint) (unlockPIN string, err error)
-	WipeHost(ctx context.Context, hostID uint) error
+	// GetHostUnlockPIN returns the PIN to unlock a macOS host that is locked
+	// or pending lock, without requesting the host to be unlocked.
+	GetHostUnlockPIN(ctx context.Context, hostID uint) (unlockPIN string, err error)
+	// WipeHost requests the host to be wiped. The confirmation and approval
+	// work the same as for LockHost.
+	WipeHost(ctx context.Context, hostID uint, confirmation string) (pendingApproval bool, err error)
+	// IsolateHost requests the host to be isolated from the network, only
+	// keeping the connection to the Fleet server so that it can be managed
+	// and released from isolation with UnisolateHost.
+	IsolateHost(ctx context.Context, hostID uint) error
+	UnisolateHost(ctx context.Context, hostID uint) error
+
+	// EnableHostLostMode sends the command to put the supervised iOS or iPadOS
+	// host in Lost Mode, locking it and displaying the settings on its lock
+	// screen.
+	EnableHostLostMode(ctx context.Context, hostID uint, settings HostLostModeSettings) error
+	// DisableHostLostMode sends the command to take the host out of Lost Mode.
+	DisableHostLostMode(ctx context.Context, hostID uint) error
+	// GetHostLostMode returns the Lost Mode status of the host, with the
+	// disabled status if the host was never put in Lost Mode.
+	GetHostLostMode(ctx context.Context, hostID uint) (*HostLostMode, error)
+	// RequestHostLocation sends the command to get the location of the host
+	// in Lost Mode, which is stored when the host responds.
+	RequestHostLocation(ctx context.Context, hostID uint) error
+	// GetHostLastLocation returns the last location reported by the host.
+	GetHostLastLocation(ctx context.Context, hostID uint) (*HostMDMAppleLocation, error)
+
+	// GetHostActivationLockBypassCode returns the Activation Lock bypass code
+	// escrowed by the Apple host.
+	GetHostActivationLockBypassCode(ctx context.Context, hostID uint) (*HostMDMAppleActivationLockBypassCode, error)
+	// ClearHostActivationLockBypassCode sends the command to clear the
+	// Activation Lock bypass code of the host, the escrowed code is deleted
+	// when the host acknowledges it.
+	ClearHostActivationLockBypassCode(ctx context.Context, hostID uint) error
+
+	// GetHostRecoveryLockPassword returns the Recovery Lock password set by
+	// Fleet on the Apple silicon Mac.
+	GetHostRecoveryLockPassword(ctx context.Context, hostID uint) (*HostMDMAppleRecoveryLockPassword, error)
+
+	// SetHostFirmwarePassword sends the SetFirmwarePassword command to the
+	// Intel Mac to set a new random firmware password, escrowed by Fleet.
+	SetHostFirmwarePassword(ctx context.Context, hostID uint) error
+
+	// GetHostFirmwarePassword returns the firmware password set by Fleet on
+	// the Intel Mac.
+	GetHostFirmwarePassword(ctx context.Context, hostID uint) (*HostMDMAppleFirmwarePassword, error)
 }
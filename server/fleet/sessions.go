@@ -66,6 +66,12 @@ type Session struct {
 	UserID     uint      `json:"user_id" db:"user_id"`
 	Key        string
 	APIOnly    *bool `json:"-" db:"api_only"`
+	// IPAddress is the public IP address of the client that created the
+	// session.
+	IPAddress string `json:"ip_address" db:"ip_address"`
+	// UserAgent is the User-Agent header of the client that created the
+	// session.
+	UserAgent string `json:"user_agent" db:"user_agent"`
 }
 
 func (s Session) AuthzType() string {
@@ -0,0 +1,30 @@
+package fleet
+
+// SharedLibrary is the authz type used to check access control to the shared
+// library, i.e. the "no team" scripts and configuration profiles that a
+// global admin shared with all teams. TeamID is nil to share items with all
+// teams, or the team that opts out of shared items.
+type SharedLibrary struct {
+	TeamID *uint `json:"team_id"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (SharedLibrary) AuthzType() string {
+	return "shared_library"
+}
+
+// TeamSharedLibraryOptOuts are the shared scripts and configuration profiles
+// that a team opted out of. They are not part of the library of the team, the
+// shared scripts cannot be run on the hosts of the team and the shared
+// profiles are not delivered to them.
+type TeamSharedLibraryOptOuts struct {
+	ScriptIDs []uint `json:"script_ids"`
+	// ProfileUUIDs are the UUIDs of Apple and Windows configuration profiles.
+	ProfileUUIDs []string `json:"profile_uuids"`
+}
+
+// SharedLibraryItemUpdate is the payload to share a "no team" script or
+// configuration profile with all teams, or to stop sharing it.
+type SharedLibraryItemUpdate struct {
+	SharedWithAllTeams *bool `json:"shared_with_all_teams"`
+}
@@ -30,6 +30,62 @@ const (
 	SoftwareArchMaxLength    = 16
 )
 
+// SoftwareSourceMDM is the source of the software reported via the
+// InstalledApplicationList MDM command by the Apple hosts that are not
+// enrolled in osquery.
+const SoftwareSourceMDM = "mdm"
+
+// SoftwareInventorySource is the channel that reported the software installed
+// on a host. It is unrelated to the Source of the software, which is the
+// osquery table (or MDM command) the software was read from.
+type SoftwareInventorySource string
+
+const (
+	// SoftwareInventorySourceOsquery is the software reported by the osquery
+	// software detail query.
+	SoftwareInventorySourceOsquery SoftwareInventorySource = "osquery"
+	// SoftwareInventorySourceMDM is the software reported by the
+	// InstalledApplicationList MDM command.
+	SoftwareInventorySourceMDM SoftwareInventorySource = "mdm"
+	// SoftwareInventorySourceInstaller is the software reported as installed
+	// by the fleetd installer.
+	SoftwareInventorySourceInstaller SoftwareInventorySource = "installer"
+)
+
+// Precedence returns the precedence of the source when several sources report
+// different versions of the same software on a host, the version reported by
+// the source of highest precedence is the one installed on the host.
+func (s SoftwareInventorySource) Precedence() int {
+	switch s {
+	case SoftwareInventorySourceOsquery:
+		return 3
+	case SoftwareInventorySourceMDM:
+		return 2
+	case SoftwareInventorySourceInstaller:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsFullInventory returns true if the source reports the full list of
+// software installed on the host, in which case its report replaces the
+// software it previously reported. The installer only reports the software it
+// installed.
+func (s SoftwareInventorySource) IsFullInventory() bool {
+	return s == SoftwareInventorySourceOsquery || s == SoftwareInventorySourceMDM
+}
+
+// HostSoftwareInventorySource is a source that reported a software installed
+// on a host.
+type HostSoftwareInventorySource struct {
+	Source SoftwareInventorySource `json:"source"`
+	// Version is the version reported by the source, it differs from the
+	// version of the software when a source of higher precedence reported
+	// another version.
+	Version string `json:"version"`
+}
+
 type Vulnerabilities []CVE
 
 // Software is a named and versioned piece of software installed on a device.
@@ -120,6 +176,8 @@ func (c *SliceString) Scan(v interface{}) error {
 	switch tv := v.(type) {
 	case []byte:
 		return json.Unmarshal(tv, &c)
+	case nil: // sql NULL
+		return nil
 	}
 	return errors.New("unsupported type")
 }
@@ -185,6 +243,9 @@ type HostSoftwareEntry struct {
 	// Where this software was installed on the host, value is derived from the
 	// host_software_installed_paths table.
 	InstalledPaths []string `json:"installed_paths"`
+	// InventorySources are the sources that reported the software on the host,
+	// ordered by precedence.
+	InventorySources []HostSoftwareInventorySource `json:"inventory_sources,omitempty"`
 }
 
 // HostSoftware is the set of software installed on a specific host
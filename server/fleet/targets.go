@@ -56,6 +56,11 @@ type TargetMetrics struct {
 //
 //	When provided, team IDs are OR'ed on the selection.
 //	When provided together with LabelIDs then they are AND'ed on the selection.
+//
+// HostGroupIDs
+//
+//	Hosts that are members of any of the host groups are selected, the same
+//	way as if they were included in HostIDs.
 type HostTargets struct {
 	// HostIDs is the IDs of hosts to be targeted.
 	HostIDs []uint `json:"hosts"`
@@ -63,6 +68,8 @@ type HostTargets struct {
 	LabelIDs []uint `json:"labels"`
 	// TeamIDs is the IDs of teams to be targeted.
 	TeamIDs []uint `json:"teams"`
+	// HostGroupIDs is the IDs of host groups to be targeted.
+	HostGroupIDs []uint `json:"host_groups"`
 }
 
 type TargetType int
@@ -71,6 +78,7 @@ const (
 	TargetLabel TargetType = iota
 	TargetHost
 	TargetTeam
+	TargetHostGroup
 )
 
 func (t TargetType) String() string {
@@ -81,6 +89,8 @@ func (t TargetType) String() string {
 		return "host"
 	case TargetTeam:
 		return "team"
+	case TargetHostGroup:
+		return "host_group"
 	default:
 		return fmt.Sprintf("unknown: %d", t)
 	}
@@ -94,6 +104,8 @@ func ParseTargetType(s string) (TargetType, error) {
 		return TargetHost, nil
 	case "team":
 		return TargetTeam, nil
+	case "host_group":
+		return TargetHostGroup, nil
 	default:
 		return 0, fmt.Errorf("invalid TargetType: %s", s)
 	}
@@ -0,0 +1,96 @@
+package fleet
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TeamSettingSource indicates where the value of a team setting comes from.
+type TeamSettingSource string
+
+const (
+	// TeamSettingInherited is the source of a team setting that inherits the
+	// value of the global configuration.
+	TeamSettingInherited TeamSettingSource = "inherited"
+	// TeamSettingOverridden is the source of a team setting that is set on the
+	// team itself.
+	TeamSettingOverridden TeamSettingSource = "overridden"
+)
+
+// inheritableTeamSettings maps the keys of the team settings that can inherit
+// the global configuration to the function that copies the global value to
+// the team configuration.
+var inheritableTeamSettings = map[string]func(tc *TeamConfig, ac *AppConfig){
+	"webhook_settings.host_status_webhook": func(tc *TeamConfig, ac *AppConfig) {
+		settings := ac.WebhookSettings.HostStatusWebhook
+		tc.WebhookSettings.HostStatusWebhook = &settings
+	},
+	"webhook_settings.failing_policies_webhook": func(tc *TeamConfig, ac *AppConfig) {
+		// policies are specific to the team, so whether the webhook is enabled
+		// and for which policies is always set on the team, only the
+		// destination is inherited.
+		tc.WebhookSettings.FailingPoliciesWebhook.DestinationURL = ac.WebhookSettings.FailingPoliciesWebhook.DestinationURL
+		tc.WebhookSettings.FailingPoliciesWebhook.HostBatchSize = ac.WebhookSettings.FailingPoliciesWebhook.HostBatchSize
+	},
+	"file_retrieval": func(tc *TeamConfig, ac *AppConfig) {
+		tc.FileRetrieval = ac.FileRetrieval
+		if ac.FileRetrieval.AllowedPaths != nil {
+			tc.FileRetrieval.AllowedPaths = append([]string{}, ac.FileRetrieval.AllowedPaths...)
+		}
+	},
+	"remote_queries": func(tc *TeamConfig, ac *AppConfig) {
+		tc.RemoteQueries = ac.RemoteQueries
+	},
+	"vulnerability_sla": func(tc *TeamConfig, ac *AppConfig) {
+		tc.VulnerabilitySLA = ac.VulnerabilitySLA
+	},
+}
+
+// InheritableTeamSettings returns the sorted keys of the team settings that
+// can inherit the global configuration.
+func InheritableTeamSettings() []string {
+	keys := make([]string, 0, len(inheritableTeamSettings))
+	for k := range inheritableTeamSettings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidateInheritedSettings validates the keys of the team settings that
+// inherit the global configuration, errors are added to invalid.
+func ValidateInheritedSettings(keys []string, invalid *InvalidArgumentError) {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := inheritableTeamSettings[k]; !ok {
+			invalid.Append("inherited_settings", fmt.Sprintf("%q can't be inherited, supported settings are: %v", k, InheritableTeamSettings()))
+			continue
+		}
+		if seen[k] {
+			invalid.Append("inherited_settings", fmt.Sprintf("%q is specified more than once", k))
+		}
+		seen[k] = true
+	}
+}
+
+// ApplyInheritedSettings replaces the team settings that inherit the global
+// configuration with the values of appConfig, and records in
+// SettingsSources whether each inheritable setting is inherited or
+// overridden by the team. It must only be used to resolve the effective
+// settings of the team, not before saving the team.
+func (t *Team) ApplyInheritedSettings(appConfig *AppConfig) {
+	inherited := make(map[string]bool, len(t.Config.InheritedSettings))
+	for _, k := range t.Config.InheritedSettings {
+		inherited[k] = true
+	}
+
+	t.SettingsSources = make(map[string]TeamSettingSource, len(inheritableTeamSettings))
+	for k, apply := range inheritableTeamSettings {
+		if !inherited[k] {
+			t.SettingsSources[k] = TeamSettingOverridden
+			continue
+		}
+		apply(&t.Config, appConfig)
+		t.SettingsSources[k] = TeamSettingInherited
+	}
+}
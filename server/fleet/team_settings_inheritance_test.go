@@ -0,0 +1,85 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamApplyInheritedSettings(t *testing.T) {
+	appConfig := &AppConfig{
+		FileRetrieval:    FileRetrievalSettings{AllowedPaths: []string{"/var/log/"}},
+		RemoteQueries:    RemoteQuerySettings{Enabled: true},
+		VulnerabilitySLA: VulnerabilitySLASettings{Enable: true, CriticalDays: 14},
+	}
+	appConfig.WebhookSettings.HostStatusWebhook = HostStatusWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://global.example/hosts",
+		DaysCount:      3,
+	}
+	appConfig.WebhookSettings.FailingPoliciesWebhook = FailingPoliciesWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://global.example/policies",
+		PolicyIDs:      []uint{1, 2},
+		HostBatchSize:  10,
+	}
+
+	// nothing is inherited by default
+	team := &Team{Config: TeamConfig{RemoteQueries: RemoteQuerySettings{Enabled: false}}}
+	team.ApplyInheritedSettings(appConfig)
+	require.Nil(t, team.Config.WebhookSettings.HostStatusWebhook)
+	require.False(t, team.Config.RemoteQueries.Enabled)
+	require.Len(t, team.SettingsSources, len(InheritableTeamSettings()))
+	for _, k := range InheritableTeamSettings() {
+		require.Equal(t, TeamSettingOverridden, team.SettingsSources[k], k)
+	}
+
+	team = &Team{Config: TeamConfig{
+		InheritedSettings: []string{"webhook_settings.host_status_webhook", "webhook_settings.failing_policies_webhook", "file_retrieval", "vulnerability_sla"},
+		RemoteQueries:     RemoteQuerySettings{Enabled: false},
+	}}
+	team.Config.WebhookSettings.FailingPoliciesWebhook = FailingPoliciesWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://team.example/policies",
+		PolicyIDs:      []uint{3},
+	}
+	team.ApplyInheritedSettings(appConfig)
+
+	require.Equal(t, &appConfig.WebhookSettings.HostStatusWebhook, team.Config.WebhookSettings.HostStatusWebhook)
+	// the policies of the failing policies webhook stay the team's
+	require.Equal(t, FailingPoliciesWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://global.example/policies",
+		PolicyIDs:      []uint{3},
+		HostBatchSize:  10,
+	}, team.Config.WebhookSettings.FailingPoliciesWebhook)
+	require.Equal(t, []string{"/var/log/"}, team.Config.FileRetrieval.AllowedPaths)
+	require.False(t, team.Config.RemoteQueries.Enabled)
+	require.Equal(t, appConfig.VulnerabilitySLA, team.Config.VulnerabilitySLA)
+	require.Equal(t, map[string]TeamSettingSource{
+		"webhook_settings.host_status_webhook":      TeamSettingInherited,
+		"webhook_settings.failing_policies_webhook": TeamSettingInherited,
+		"file_retrieval":    TeamSettingInherited,
+		"remote_queries":    TeamSettingOverridden,
+		"vulnerability_sla": TeamSettingInherited,
+	}, team.SettingsSources)
+
+	// the global config is not modified through the team
+	team.Config.FileRetrieval.AllowedPaths[0] = "/tmp/"
+	team.Config.WebhookSettings.HostStatusWebhook.DaysCount = 10
+	require.Equal(t, []string{"/var/log/"}, appConfig.FileRetrieval.AllowedPaths)
+	require.Equal(t, 3, appConfig.WebhookSettings.HostStatusWebhook.DaysCount)
+}
+
+func TestValidateInheritedSettings(t *testing.T) {
+	invalid := &InvalidArgumentError{}
+	ValidateInheritedSettings(nil, invalid)
+	ValidateInheritedSettings(InheritableTeamSettings(), invalid)
+	require.False(t, invalid.HasErrors())
+
+	ValidateInheritedSettings([]string{"remote_queries", "features", "remote_queries"}, invalid)
+	errs := invalid.Invalid()
+	require.Len(t, errs, 2)
+	require.Contains(t, errs[0]["reason"], `"features" can't be inherited`)
+	require.Equal(t, `"remote_queries" is specified more than once`, errs[1]["reason"])
+}
@@ -19,13 +19,20 @@ const (
 )
 
 type TeamPayload struct {
-	Name               *string              `json:"name"`
-	Description        *string              `json:"description"`
-	Secrets            []*EnrollSecret      `json:"secrets"`
-	WebhookSettings    *TeamWebhookSettings `json:"webhook_settings"`
-	Integrations       *TeamIntegrations    `json:"integrations"`
-	MDM                *TeamPayloadMDM      `json:"mdm"`
-	HostExpirySettings *HostExpirySettings  `json:"host_expiry_settings"`
+	Name               *string                   `json:"name"`
+	Description        *string                   `json:"description"`
+	Secrets            []*EnrollSecret           `json:"secrets"`
+	WebhookSettings    *TeamWebhookSettings      `json:"webhook_settings"`
+	Integrations       *TeamIntegrations         `json:"integrations"`
+	MDM                *TeamPayloadMDM           `json:"mdm"`
+	HostExpirySettings *HostExpirySettings       `json:"host_expiry_settings"`
+	FileRetrieval      *FileRetrievalSettings    `json:"file_retrieval"`
+	LockWipeSettings   *LockWipeSettings         `json:"lock_wipe_settings"`
+	RemoteQueries      *RemoteQuerySettings      `json:"remote_queries"`
+	VulnerabilitySLA   *VulnerabilitySLASettings `json:"vulnerability_sla"`
+	Localization       *LocalizationSettings     `json:"localization"`
+	Branding           *OrgInfo                  `json:"branding"`
+	InheritedSettings  *[]string                 `json:"inherited_settings"`
 	// Note AgentOptions must be set by a separate endpoint.
 }
 
@@ -55,6 +62,9 @@ type Team struct {
 	// Description is an optional description for the team.
 	Description string     `json:"description" db:"description"`
 	Config      TeamConfig `json:"-" db:"config"` // see json.MarshalJSON/UnmarshalJSON implementations
+	// TenantID is the ID of the tenant of the team, nil if the team doesn't
+	// belong to a tenant.
+	TenantID *uint `json:"-" db:"tenant_id"`
 
 	// Derived from JOINs
 
@@ -68,6 +78,10 @@ type Team struct {
 	Hosts []Host `json:"hosts,omitempty"`
 	// Secrets is the enroll secrets valid for this team.
 	Secrets []*EnrollSecret `json:"secrets,omitempty"`
+	// SettingsSources indicates for each setting that can inherit the global
+	// configuration if it is inherited or overridden by the team. It is only
+	// set once the inherited settings are applied.
+	SettingsSources map[string]TeamSettingSource `json:"settings_sources,omitempty" db:"-"`
 }
 
 func (t Team) MarshalJSON() ([]byte, error) {
@@ -86,6 +100,8 @@ func (t Team) MarshalJSON() ([]byte, error) {
 		HostCount   int             `json:"host_count"`
 		Hosts       []HostResponse  `json:"hosts,omitempty"`
 		Secrets     []*EnrollSecret `json:"secrets,omitempty"`
+
+		SettingsSources map[string]TeamSettingSource `json:"settings_sources,omitempty"`
 	}{
 		ID:          t.ID,
 		CreatedAt:   t.CreatedAt,
@@ -97,6 +113,8 @@ func (t Team) MarshalJSON() ([]byte, error) {
 		HostCount:   t.HostCount,
 		Hosts:       HostResponsesForHostsCheap(t.Hosts),
 		Secrets:     t.Secrets,
+
+		SettingsSources: t.SettingsSources,
 	}
 
 	return json.Marshal(x)
@@ -114,6 +132,8 @@ func (t *Team) UnmarshalJSON(b []byte) error {
 		HostCount   int             `json:"host_count"`
 		Hosts       []Host          `json:"hosts,omitempty"`
 		Secrets     []*EnrollSecret `json:"secrets,omitempty"`
+
+		SettingsSources map[string]TeamSettingSource `json:"settings_sources,omitempty"`
 	}
 
 	if err := json.Unmarshal(b, &x); err != nil {
@@ -134,6 +154,8 @@ func (t *Team) UnmarshalJSON(b []byte) error {
 		HostCount:   x.HostCount,
 		Hosts:       x.Hosts,
 		Secrets:     x.Secrets,
+
+		SettingsSources: x.SettingsSources,
 	}
 
 	return nil
@@ -141,13 +163,25 @@ func (t *Team) UnmarshalJSON(b []byte) error {
 
 type TeamConfig struct {
 	// AgentOptions is the options for osquery and Orbit.
-	AgentOptions       *json.RawMessage      `json:"agent_options,omitempty"`
-	HostExpirySettings HostExpirySettings    `json:"host_expiry_settings"`
-	WebhookSettings    TeamWebhookSettings   `json:"webhook_settings"`
-	Integrations       TeamIntegrations      `json:"integrations"`
-	Features           Features              `json:"features"`
-	MDM                TeamMDM               `json:"mdm"`
-	Scripts            optjson.Slice[string] `json:"scripts,omitempty"`
+	AgentOptions       *json.RawMessage         `json:"agent_options,omitempty"`
+	HostExpirySettings HostExpirySettings       `json:"host_expiry_settings"`
+	WebhookSettings    TeamWebhookSettings      `json:"webhook_settings"`
+	Integrations       TeamIntegrations         `json:"integrations"`
+	Features           Features                 `json:"features"`
+	MDM                TeamMDM                  `json:"mdm"`
+	Scripts            optjson.Slice[string]    `json:"scripts,omitempty"`
+	FileRetrieval      FileRetrievalSettings    `json:"file_retrieval"`
+	LockWipeSettings   LockWipeSettings         `json:"lock_wipe_settings"`
+	RemoteQueries      RemoteQuerySettings      `json:"remote_queries"`
+	VulnerabilitySLA   VulnerabilitySLASettings `json:"vulnerability_sla"`
+	Localization       LocalizationSettings     `json:"localization"`
+	// Branding overrides the branding settings of the organization for the
+	// hosts of the team, empty settings use the organization's.
+	Branding OrgInfo `json:"branding"`
+	// InheritedSettings is the list of settings (e.g.
+	// "webhook_settings.host_status_webhook") for which the team uses the
+	// global configuration instead of its own.
+	InheritedSettings []string `json:"inherited_settings,omitempty"`
 }
 
 type TeamWebhookSettings struct {
@@ -199,6 +233,10 @@ func (t *TeamMDM) Copy() *TeamMDM {
 	if t.MacOSSettings.DeprecatedEnableDiskEncryption != nil {
 		clone.MacOSSettings.DeprecatedEnableDiskEncryption = ptr.Bool(*t.MacOSSettings.DeprecatedEnableDiskEncryption)
 	}
+	if t.MacOSSettings.EraseDevice != nil {
+		opts := *t.MacOSSettings.EraseDevice
+		clone.MacOSSettings.EraseDevice = &opts
+	}
 	if t.WindowsSettings.CustomSettings.Set {
 		windowsSettings := make([]MDMProfileSpec, len(t.WindowsSettings.CustomSettings.Value))
 		for i, mps := range t.WindowsSettings.CustomSettings.Value {
@@ -404,14 +442,21 @@ type TeamSpec struct {
 	// If the agent_options key is present but empty in the YAML, will be set to
 	// "null" (JSON null). Otherwise, if the key is present and set, it will be
 	// set to the agent options JSON object.
-	AgentOptions       json.RawMessage         `json:"agent_options,omitempty"` // marshals as "null" if omitempty is not set
-	HostExpirySettings *HostExpirySettings     `json:"host_expiry_settings,omitempty"`
-	Secrets            []EnrollSecret          `json:"secrets,omitempty"`
-	Features           *json.RawMessage        `json:"features"`
-	MDM                TeamSpecMDM             `json:"mdm"`
-	Scripts            optjson.Slice[string]   `json:"scripts"`
-	WebhookSettings    TeamSpecWebhookSettings `json:"webhook_settings"`
-	Integrations       TeamSpecIntegrations    `json:"integrations"`
+	AgentOptions       json.RawMessage           `json:"agent_options,omitempty"` // marshals as "null" if omitempty is not set
+	HostExpirySettings *HostExpirySettings       `json:"host_expiry_settings,omitempty"`
+	FileRetrieval      *FileRetrievalSettings    `json:"file_retrieval,omitempty"`
+	LockWipeSettings   *LockWipeSettings         `json:"lock_wipe_settings,omitempty"`
+	RemoteQueries      *RemoteQuerySettings      `json:"remote_queries,omitempty"`
+	VulnerabilitySLA   *VulnerabilitySLASettings `json:"vulnerability_sla,omitempty"`
+	Localization       *LocalizationSettings     `json:"localization,omitempty"`
+	Branding           *OrgInfo                  `json:"branding,omitempty"`
+	InheritedSettings  *[]string                 `json:"inherited_settings,omitempty"`
+	Secrets            []EnrollSecret            `json:"secrets,omitempty"`
+	Features           *json.RawMessage          `json:"features"`
+	MDM                TeamSpecMDM               `json:"mdm"`
+	Scripts            optjson.Slice[string]     `json:"scripts"`
+	WebhookSettings    TeamSpecWebhookSettings   `json:"webhook_settings"`
+	Integrations       TeamSpecIntegrations      `json:"integrations"`
 }
 
 type TeamSpecWebhookSettings struct {
@@ -449,6 +494,10 @@ func TeamSpecFromTeam(t *Team) (*TeamSpec, error) {
 	mdmSpec.WindowsUpdates = t.Config.MDM.WindowsUpdates
 	mdmSpec.MacOSSettings = t.Config.MDM.MacOSSettings.ToMap()
 	delete(mdmSpec.MacOSSettings, "enable_disk_encryption")
+	if t.Config.MDM.MacOSSettings.EraseDevice == nil {
+		// the erase device options are omitted when the defaults are used
+		delete(mdmSpec.MacOSSettings, "erase_device")
+	}
 	mdmSpec.MacOSSetup = t.Config.MDM.MacOSSetup
 	mdmSpec.EnableDiskEncryption = optjson.SetBool(t.Config.MDM.EnableDiskEncryption)
 	mdmSpec.WindowsSettings = t.Config.MDM.WindowsSettings
@@ -463,6 +512,11 @@ func TeamSpecFromTeam(t *Team) (*TeamSpec, error) {
 		integrations.GoogleCalendar = t.Config.Integrations.GoogleCalendar
 	}
 
+	var inheritedSettings *[]string
+	if len(t.Config.InheritedSettings) > 0 {
+		inheritedSettings = &t.Config.InheritedSettings
+	}
+
 	return &TeamSpec{
 		Name:               t.Name,
 		AgentOptions:       agentOptions,
@@ -470,6 +524,13 @@ func TeamSpecFromTeam(t *Team) (*TeamSpec, error) {
 		Secrets:            secrets,
 		MDM:                mdmSpec,
 		HostExpirySettings: &t.Config.HostExpirySettings,
+		FileRetrieval:      &t.Config.FileRetrieval,
+		LockWipeSettings:   &t.Config.LockWipeSettings,
+		RemoteQueries:      &t.Config.RemoteQueries,
+		VulnerabilitySLA:   &t.Config.VulnerabilitySLA,
+		Localization:       &t.Config.Localization,
+		Branding:           &t.Config.Branding,
+		InheritedSettings:  inheritedSettings,
 		WebhookSettings:    webhookSettings,
 		Integrations:       integrations,
 	}, nil
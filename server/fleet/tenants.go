@@ -0,0 +1,120 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Tenant is a customer of a Fleet server shared by multiple customers (e.g.
+// by a managed service provider). A tenant groups teams and users, the users
+// of a tenant only have access to the hosts of the tenant's teams.
+type Tenant struct {
+	ID          uint         `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Description string       `json:"description" db:"description"`
+	Config      TenantConfig `json:"config" db:"config"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+
+	// TeamCount is the number of teams of the tenant.
+	TeamCount int `json:"team_count" db:"team_count"`
+	// UserCount is the number of users of the tenant.
+	UserCount int `json:"user_count" db:"user_count"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (t *Tenant) AuthzType() string {
+	return "tenant"
+}
+
+// TenantPayload is the payload used to create or modify a tenant. When
+// modifying a tenant, the fields that are not set are left unchanged.
+type TenantPayload struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Config      *TenantConfig `json:"config"`
+}
+
+// TenantAPNsPayload is the payload used to set the APNs certificate and key
+// of a tenant, both PEM-encoded.
+type TenantAPNsPayload struct {
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// TenantConfig is the tenant-scoped configuration, it overrides the global
+// configuration for the tenant. Unset fields inherit the global value.
+type TenantConfig struct {
+	OrgInfo            *OrgInfo            `json:"org_info,omitempty"`
+	HostExpirySettings *HostExpirySettings `json:"host_expiry_settings,omitempty"`
+	SMTPSettings       *SMTPSettings       `json:"smtp_settings,omitempty"`
+}
+
+// ApplyTo returns a copy of the global app config with the tenant's
+// overrides applied.
+func (c TenantConfig) ApplyTo(appCfg *AppConfig) *AppConfig {
+	tenantCfg := appCfg.Copy()
+	if c.OrgInfo != nil {
+		tenantCfg.OrgInfo = *c.OrgInfo
+	}
+	if c.HostExpirySettings != nil {
+		tenantCfg.HostExpirySettings = *c.HostExpirySettings
+	}
+	if c.SMTPSettings != nil {
+		smtp := *c.SMTPSettings
+		tenantCfg.SMTPSettings = &smtp
+	}
+	return tenantCfg
+}
+
+// Scan implements the sql.Scanner interface
+func (c *TenantConfig) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (c TenantConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// TenantMDMAssetName is the name of an MDM certificate, key or token of a
+// tenant. The assets are encrypted with the tenant's encryption key.
+type TenantMDMAssetName string
+
+const (
+	TenantMDMAssetAPNSCert TenantMDMAssetName = "apns_cert"
+	TenantMDMAssetAPNSKey  TenantMDMAssetName = "apns_key"
+	TenantMDMAssetSCEPCert TenantMDMAssetName = "scep_cert"
+	TenantMDMAssetSCEPKey  TenantMDMAssetName = "scep_key"
+	TenantMDMAssetABMCert  TenantMDMAssetName = "abm_cert"
+	TenantMDMAssetABMKey   TenantMDMAssetName = "abm_key"
+	TenantMDMAssetABMToken TenantMDMAssetName = "abm_token"
+)
+
+// TenantMDMAssetNames lists the names of the MDM assets of a tenant.
+var TenantMDMAssetNames = []TenantMDMAssetName{
+	TenantMDMAssetAPNSCert,
+	TenantMDMAssetAPNSKey,
+	TenantMDMAssetSCEPCert,
+	TenantMDMAssetSCEPKey,
+	TenantMDMAssetABMCert,
+	TenantMDMAssetABMKey,
+	TenantMDMAssetABMToken,
+}
+
+// TenantMDMAsset is an MDM certificate, key or token of a tenant.
+type TenantMDMAsset struct {
+	Name  TenantMDMAssetName `db:"name"`
+	Value []byte             `db:"value"`
+}
@@ -0,0 +1,35 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantConfigApplyTo(t *testing.T) {
+	appCfg := &AppConfig{
+		OrgInfo:            OrgInfo{OrgName: "MSP"},
+		HostExpirySettings: HostExpirySettings{HostExpiryEnabled: true, HostExpiryWindow: 30},
+		SMTPSettings:       &SMTPSettings{SMTPSenderAddress: "msp@example.com"},
+	}
+
+	// no overrides, the global config is inherited
+	got := TenantConfig{}.ApplyTo(appCfg)
+	require.Equal(t, appCfg, got)
+	require.NotSame(t, appCfg, got)
+
+	cfg := TenantConfig{
+		OrgInfo:      &OrgInfo{OrgName: "Customer"},
+		SMTPSettings: &SMTPSettings{SMTPSenderAddress: "customer@example.com"},
+	}
+	got = cfg.ApplyTo(appCfg)
+	require.Equal(t, "Customer", got.OrgInfo.OrgName)
+	require.Equal(t, "customer@example.com", got.SMTPSettings.SMTPSenderAddress)
+	require.Equal(t, appCfg.HostExpirySettings, got.HostExpirySettings)
+
+	// the global config is not modified
+	require.Equal(t, "MSP", appCfg.OrgInfo.OrgName)
+	require.Equal(t, "msp@example.com", appCfg.SMTPSettings.SMTPSenderAddress)
+	got.SMTPSettings.SMTPSenderAddress = "changed"
+	require.Equal(t, "customer@example.com", cfg.SMTPSettings.SMTPSenderAddress)
+}
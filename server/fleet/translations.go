@@ -0,0 +1,247 @@
+package fleet
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale of the compiled-in end-user facing messages.
+const DefaultLocale = "en"
+
+// MessageKey identifies an end-user facing message produced by Fleet that can
+// be translated.
+type MessageKey string
+
+const (
+	// Fleet Desktop menu items.
+	MessageDesktopConnecting       MessageKey = "desktop.connecting"
+	MessageDesktopMyDevice         MessageKey = "desktop.my_device"
+	MessageDesktopMyDeviceOneIssue MessageKey = "desktop.my_device_one_issue"
+	MessageDesktopMyDeviceIssues   MessageKey = "desktop.my_device_issues"
+	MessageDesktopTransparency     MessageKey = "desktop.transparency"
+	MessageDesktopMigrateMDM       MessageKey = "desktop.migrate_mdm"
+
+	// macOS MDM migration dialogs.
+	MessageMDMMigrationTitle        MessageKey = "mdm_migration.title"
+	MessageMDMMigrationInstructions MessageKey = "mdm_migration.instructions"
+	MessageMDMMigrationReminder     MessageKey = "mdm_migration.reminder"
+	MessageMDMMigrationStart        MessageKey = "mdm_migration.start"
+	MessageMDMMigrationLater        MessageKey = "mdm_migration.later"
+	MessageMDMMigrationContactIT    MessageKey = "mdm_migration.contact_it"
+	MessageMDMMigrationUnenrolling  MessageKey = "mdm_migration.unenrolling"
+	MessageMDMMigrationErrorTitle   MessageKey = "mdm_migration.error_title"
+	MessageMDMMigrationErrorContact MessageKey = "mdm_migration.error_contact"
+	MessageMDMMigrationClose        MessageKey = "mdm_migration.close"
+
+	// Windows MDM enrollment pages.
+	MessageWindowsEnrollmentTOSTitle  MessageKey = "windows_enrollment.tos_title"
+	MessageWindowsEnrollmentTOSIntro  MessageKey = "windows_enrollment.tos_intro"
+	MessageWindowsEnrollmentTOSAccept MessageKey = "windows_enrollment.tos_accept"
+)
+
+// DefaultMessages are the compiled-in (English) end-user facing messages.
+// Placeholders are written as {name} and are replaced when the message is
+// formatted.
+var DefaultMessages = map[MessageKey]string{
+	MessageDesktopConnecting:       "Connecting...",
+	MessageDesktopMyDevice:         "My device",
+	MessageDesktopMyDeviceOneIssue: "My device (1 issue)",
+	MessageDesktopMyDeviceIssues:   "My device ({count} issues)",
+	MessageDesktopTransparency:     "Transparency",
+	MessageDesktopMigrateMDM:       "Migrate to Fleet",
+
+	MessageMDMMigrationTitle:        "Migrate to Fleet",
+	MessageMDMMigrationInstructions: "Select **Start** and look for this notification in your notification center:",
+	MessageMDMMigrationReminder:     "After you start, this window will popup every 15-20 minutes until you finish.",
+	MessageMDMMigrationStart:        "Start",
+	MessageMDMMigrationLater:        "Later",
+	MessageMDMMigrationContactIT:    "Unsure? Contact IT",
+	MessageMDMMigrationUnenrolling:  "Unenrolling you from your old MDM. This could take 90 seconds...",
+	MessageMDMMigrationErrorTitle:   "Something's gone wrong.",
+	MessageMDMMigrationErrorContact: "Please contact your IT admin [here]({contact_url}).",
+	MessageMDMMigrationClose:        "Close",
+
+	MessageWindowsEnrollmentTOSTitle:  "Terms and conditions",
+	MessageWindowsEnrollmentTOSIntro:  "Fleet is open-source software for managing computers. Your organization is using it to manage and secure this computer.",
+	MessageWindowsEnrollmentTOSAccept: "Agree and continue",
+}
+
+// MaxTranslatedMessageLength is the maximum length of a translated message.
+const MaxTranslatedMessageLength = 1024
+
+var messagePlaceholderRegexp = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// messagePlaceholders returns the names of the placeholders used by msg.
+func messagePlaceholders(msg string) map[string]bool {
+	matches := messagePlaceholderRegexp.FindAllStringSubmatch(msg, -1)
+	names := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		names[m[1]] = true
+	}
+	return names
+}
+
+// LocalizationSettings holds the locale of the end-user facing messages.
+type LocalizationSettings struct {
+	// Locale is the BCP 47 language tag (e.g. "fr" or "pt-BR") of the
+	// end-user facing messages. For teams, an empty locale means that the
+	// global locale is used.
+	Locale string `json:"locale"`
+}
+
+// Validate validates and canonicalizes the locale of the settings.
+func (s *LocalizationSettings) Validate() error {
+	if s.Locale == "" {
+		return nil
+	}
+	locale, err := CanonicalLocale(s.Locale)
+	if err != nil {
+		return err
+	}
+	s.Locale = locale
+	return nil
+}
+
+// CanonicalLocale returns the canonical form of the BCP 47 language tag, e.g.
+// "pt-BR" for "pt_br".
+func CanonicalLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %q", locale)
+	}
+	return tag.String(), nil
+}
+
+// ResolveLocale returns the locale of the end-user facing messages for hosts
+// of the team (nil for hosts with no team).
+func ResolveLocale(appConfig *AppConfig, team *Team) string {
+	if team != nil && team.Config.Localization.Locale != "" {
+		return team.Config.Localization.Locale
+	}
+	if appConfig != nil && appConfig.Localization.Locale != "" {
+		return appConfig.Localization.Locale
+	}
+	return DefaultLocale
+}
+
+// Translations are the translations of the end-user facing messages for a
+// locale. Messages that are not translated fall back to the default messages.
+type Translations struct {
+	Locale    string                `json:"locale" db:"locale"`
+	Messages  map[MessageKey]string `json:"messages" db:"-"`
+	UpdatedAt time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// Validate validates and canonicalizes the locale of the translations, and
+// checks that the messages are known and use the placeholders of the default
+// messages.
+func (t *Translations) Validate() error {
+	invalid := &InvalidArgumentError{}
+	locale, err := CanonicalLocale(t.Locale)
+	if err != nil {
+		invalid.Append("locale", err.Error())
+	} else {
+		t.Locale = locale
+	}
+
+	keys := make([]string, 0, len(t.Messages))
+	for k := range t.Messages {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := MessageKey(k)
+		msg := t.Messages[key]
+		def, ok := DefaultMessages[key]
+		switch {
+		case !ok:
+			invalid.Append("messages."+k, "unknown message")
+			continue
+		case strings.TrimSpace(msg) == "":
+			invalid.Append("messages."+k, "must not be empty")
+			continue
+		case len(msg) > MaxTranslatedMessageLength:
+			invalid.Append("messages."+k, fmt.Sprintf("must be %d characters or less", MaxTranslatedMessageLength))
+			continue
+		}
+
+		allowed := messagePlaceholders(def)
+		for name := range messagePlaceholders(msg) {
+			if !allowed[name] {
+				invalid.Append("messages."+k, fmt.Sprintf("unknown placeholder {%s}", name))
+			}
+		}
+	}
+
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
+
+// LocalizedMessages are the end-user facing messages in a locale. Messages
+// that are not set fall back to the default messages, so the zero value is
+// usable and returns the default messages.
+type LocalizedMessages map[MessageKey]string
+
+// NewLocalizedMessages returns the messages for the locale from the available
+// translations. The translations of the base language (e.g. "pt" for
+// "pt-BR") are used for the messages that are not translated for the locale
+// itself.
+func NewLocalizedMessages(locale string, translations []*Translations) LocalizedMessages {
+	if locale == "" || locale == DefaultLocale {
+		return nil
+	}
+
+	byLocale := make(map[string]*Translations, len(translations))
+	for _, t := range translations {
+		byLocale[t.Locale] = t
+	}
+
+	msgs := make(LocalizedMessages)
+	if tag, err := language.Parse(locale); err == nil {
+		if base, conf := tag.Base(); conf != language.No && base.String() != locale {
+			if t := byLocale[base.String()]; t != nil {
+				for k, v := range t.Messages {
+					msgs[k] = v
+				}
+			}
+		}
+	}
+	if t := byLocale[locale]; t != nil {
+		for k, v := range t.Messages {
+			msgs[k] = v
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return msgs
+}
+
+// Get returns the message for key, falling back to the default message.
+func (m LocalizedMessages) Get(key MessageKey) string {
+	if msg, ok := m[key]; ok && msg != "" {
+		return msg
+	}
+	return DefaultMessages[key]
+}
+
+// Format returns the message for key with its placeholders replaced by the
+// values of args, given as name/value pairs.
+func (m LocalizedMessages) Format(key MessageKey, args ...string) string {
+	msg := m.Get(key)
+	if len(args) == 0 {
+		return msg
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+args[i]+"}", args[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
@@ -0,0 +1,108 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalLocale(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"fr", "fr", false},
+		{"pt_br", "pt-BR", false},
+		{"EN-us", "en-US", false},
+		{"zh-Hant-TW", "zh-Hant-TW", false},
+		{"", "", true},
+		{"not a locale", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			got, err := CanonicalLocale(c.in)
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestResolveLocale(t *testing.T) {
+	require.Equal(t, DefaultLocale, ResolveLocale(nil, nil))
+	require.Equal(t, DefaultLocale, ResolveLocale(&AppConfig{}, &Team{}))
+
+	appConfig := &AppConfig{Localization: LocalizationSettings{Locale: "fr"}}
+	require.Equal(t, "fr", ResolveLocale(appConfig, nil))
+	require.Equal(t, "fr", ResolveLocale(appConfig, &Team{}))
+	require.Equal(t, "de", ResolveLocale(appConfig, &Team{Config: TeamConfig{Localization: LocalizationSettings{Locale: "de"}}}))
+}
+
+func TestTranslationsValidate(t *testing.T) {
+	tr := &Translations{
+		Locale: "fr_ca",
+		Messages: map[MessageKey]string{
+			MessageDesktopMyDevice:          "Mon appareil",
+			MessageDesktopMyDeviceIssues:    "Mon appareil ({count} problèmes)",
+			MessageMDMMigrationErrorContact: "Contactez votre administrateur [ici]({contact_url}).",
+		},
+	}
+	require.NoError(t, tr.Validate())
+	require.Equal(t, "fr-CA", tr.Locale)
+
+	tr = &Translations{
+		Locale: "??",
+		Messages: map[MessageKey]string{
+			"desktop.unknown":            "Inconnu",
+			MessageDesktopMyDevice:       " ",
+			MessageDesktopMyDeviceIssues: "Mon appareil ({total} problèmes)",
+		},
+	}
+	err := tr.Validate()
+	var invalid *InvalidArgumentError
+	require.ErrorAs(t, err, &invalid)
+	require.ElementsMatch(t, []map[string]string{
+		{"name": "locale", "reason": `invalid locale "??"`},
+		{"name": "messages.desktop.my_device", "reason": "must not be empty"},
+		{"name": "messages.desktop.my_device_issues", "reason": "unknown placeholder {total}"},
+		{"name": "messages.desktop.unknown", "reason": "unknown message"},
+	}, invalid.Invalid())
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	translations := []*Translations{
+		{Locale: "pt", Messages: map[MessageKey]string{
+			MessageDesktopMyDevice:     "Meu dispositivo",
+			MessageDesktopTransparency: "Transparência",
+		}},
+		{Locale: "pt-BR", Messages: map[MessageKey]string{
+			MessageDesktopMyDevice: "Meu computador",
+		}},
+		{Locale: "fr", Messages: map[MessageKey]string{
+			MessageDesktopMyDeviceIssues: "Mon appareil ({count} problèmes)",
+		}},
+	}
+
+	// the zero value returns the default messages
+	var msgs LocalizedMessages
+	require.Equal(t, "My device", msgs.Get(MessageDesktopMyDevice))
+	require.Equal(t, "My device (3 issues)", msgs.Format(MessageDesktopMyDeviceIssues, "count", "3"))
+	require.Nil(t, NewLocalizedMessages(DefaultLocale, translations))
+	require.Nil(t, NewLocalizedMessages("de", translations))
+
+	// the locale's translations take precedence over the base language ones
+	msgs = NewLocalizedMessages("pt-BR", translations)
+	require.Equal(t, "Meu computador", msgs.Get(MessageDesktopMyDevice))
+	require.Equal(t, "Transparência", msgs.Get(MessageDesktopTransparency))
+	require.Equal(t, "Connecting...", msgs.Get(MessageDesktopConnecting))
+
+	msgs = NewLocalizedMessages("pt", translations)
+	require.Equal(t, "Meu dispositivo", msgs.Get(MessageDesktopMyDevice))
+
+	msgs = NewLocalizedMessages("fr-CA", translations)
+	require.Equal(t, "Mon appareil (2 problèmes)", msgs.Format(MessageDesktopMyDeviceIssues, "count", "2"))
+}
@@ -0,0 +1,107 @@
+package fleet
+
+import "time"
+
+const (
+	// UsageAnalyticsRetention is how long the detailed usage analytics are kept.
+	UsageAnalyticsRetention = 365 * 24 * time.Hour
+	// UsageAnalyticsDefaultDays is the default number of days covered by the
+	// usage analytics dashboard.
+	UsageAnalyticsDefaultDays = 30
+)
+
+// UsageAnalytics is the authorization target of the detailed usage
+// analytics dashboard.
+type UsageAnalytics struct{}
+
+// AuthzType implements authz.AuthzTyper.
+func (u *UsageAnalytics) AuthzType() string {
+	return "usage_analytics"
+}
+
+// UsageAnalyticsAPIRequestCount is the number of requests served for an API
+// endpoint on a given day.
+type UsageAnalyticsAPIRequestCount struct {
+	// Date is the day (in UTC) when the requests were served.
+	Date   time.Time `json:"date" db:"date"`
+	Method string    `json:"method" db:"method"`
+	// Path is the template of the API path, e.g. "/api/_version_/fleet/hosts/{id}".
+	Path  string `json:"path" db:"path"`
+	Count int    `json:"count" db:"request_count"`
+}
+
+// UsageAnalyticsHostCount is the number of hosts enrolled on a given day.
+type UsageAnalyticsHostCount struct {
+	Date     time.Time `json:"date" db:"date"`
+	NumHosts int       `json:"num_hosts" db:"num_hosts"`
+}
+
+// UsageAnalyticsFeatures summarizes the usage of the Fleet features, it is
+// computed the same way as the anonymous usage statistics.
+type UsageAnalyticsFeatures struct {
+	NumHostsEnrolled          int  `json:"num_hosts_enrolled"`
+	NumUsers                  int  `json:"num_users"`
+	NumWeeklyActiveUsers      int  `json:"num_weekly_active_users"`
+	NumTeams                  int  `json:"num_teams"`
+	NumPolicies               int  `json:"num_policies"`
+	NumLabels                 int  `json:"num_labels"`
+	SoftwareInventoryEnabled  bool `json:"software_inventory_enabled"`
+	VulnDetectionEnabled      bool `json:"vuln_detection_enabled"`
+	SystemUsersEnabled        bool `json:"system_users_enabled"`
+	HostsStatusWebHookEnabled bool `json:"hosts_status_webhook_enabled"`
+	MDMMacOsEnabled           bool `json:"mdm_macos_enabled"`
+	MDMWindowsEnabled         bool `json:"mdm_windows_enabled"`
+	HostExpiryEnabled         bool `json:"host_expiry_enabled"`
+	LiveQueryDisabled         bool `json:"live_query_disabled"`
+}
+
+// UsageAnalyticsFeaturesFromStatistics returns the features usage of the
+// usage statistics.
+func UsageAnalyticsFeaturesFromStatistics(stats StatisticsPayload) UsageAnalyticsFeatures {
+	return UsageAnalyticsFeatures{
+		NumHostsEnrolled:          stats.NumHostsEnrolled,
+		NumUsers:                  stats.NumUsers,
+		NumWeeklyActiveUsers:      stats.NumWeeklyActiveUsers,
+		NumTeams:                  stats.NumTeams,
+		NumPolicies:               stats.NumPolicies,
+		NumLabels:                 stats.NumLabels,
+		SoftwareInventoryEnabled:  stats.SoftwareInventoryEnabled,
+		VulnDetectionEnabled:      stats.VulnDetectionEnabled,
+		SystemUsersEnabled:        stats.SystemUsersEnabled,
+		HostsStatusWebHookEnabled: stats.HostsStatusWebHookEnabled,
+		MDMMacOsEnabled:           stats.MDMMacOsEnabled,
+		MDMWindowsEnabled:         stats.MDMWindowsEnabled,
+		HostExpiryEnabled:         stats.HostExpiryEnabled,
+		LiveQueryDisabled:         stats.LiveQueryDisabled,
+	}
+}
+
+// UsageAnalyticsAPIRequests is the number of API requests served on a given
+// day.
+type UsageAnalyticsAPIRequests struct {
+	Date  time.Time `json:"date" db:"date"`
+	Count int       `json:"count" db:"request_count"`
+}
+
+// UsageAnalyticsEndpointRequests is the number of requests served for an API
+// endpoint.
+type UsageAnalyticsEndpointRequests struct {
+	Method string `json:"method" db:"method"`
+	Path   string `json:"path" db:"path"`
+	Count  int    `json:"count" db:"request_count"`
+}
+
+// UsageAnalyticsDashboard is the local dashboard of the detailed usage
+// analytics, it is never sent to Fleet.
+type UsageAnalyticsDashboard struct {
+	// Since is the first day covered by the dashboard.
+	Since    time.Time              `json:"since"`
+	Features UsageAnalyticsFeatures `json:"features"`
+	// APIRequestsByDay is the total number of API requests per day, and
+	// APIRequestsByEndpoint the number of requests per endpoint over the whole
+	// period, most requested first.
+	APIRequestsByDay      []UsageAnalyticsAPIRequests      `json:"api_requests_by_day"`
+	APIRequestsByEndpoint []UsageAnalyticsEndpointRequests `json:"api_requests_by_endpoint"`
+	// HostCounts is the number of hosts enrolled per day.
+	HostCounts []UsageAnalyticsHostCount `json:"host_counts"`
+}
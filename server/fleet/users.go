@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"strconv"
 	"time"
 	"unicode"
 
@@ -27,6 +28,15 @@ type User struct {
 	SSOEnabled bool    `json:"sso_enabled" db:"sso_enabled"`
 	GlobalRole *string `json:"global_role" db:"global_role"`
 	APIOnly    bool    `json:"api_only" db:"api_only"`
+	// MFAEnabled is true if the user enrolled a second authentication factor
+	// and must provide it to log in with a password.
+	MFAEnabled bool `json:"mfa_enabled" db:"mfa_enabled"`
+	// TenantID is the tenant of the user, if any. The user only has access to
+	// the hosts and teams of its tenant, even with a global role.
+	TenantID *uint `json:"tenant_id,omitempty" db:"tenant_id"`
+	// TenantTeamIDs is the IDs of the teams of the user's tenant, it is only
+	// used for authorization.
+	TenantTeamIDs []uint `json:"-" db:"-"`
 
 	// Teams is the teams this user has roles in. For users with a global role, Teams is expected to be empty.
 	Teams []UserTeam `json:"teams"`
@@ -58,10 +68,34 @@ func (u *User) IsAdminForcedPasswordReset() bool {
 	return u.AdminForcedPasswordReset
 }
 
+// IsMFAEnrollmentRequired returns true if the user must enroll a second
+// authentication factor before using Fleet, given the MFA settings of the
+// organization. Only users that log in with a password can use MFA.
+func (u *User) IsMFAEnrollmentRequired(settings MFASettings) bool {
+	if !settings.Required || u.SSOEnabled || u.APIOnly {
+		return false
+	}
+	return !u.MFAEnabled
+}
+
 func (u *User) AuthzType() string {
 	return "user"
 }
 
+// ExtraAuthz implements authz.ExtraAuthzer.
+func (u *User) ExtraAuthz() (map[string]interface{}, error) {
+	if u.TenantID == nil {
+		return nil, nil
+	}
+	teamIDs := make([]interface{}, 0, len(u.TenantTeamIDs))
+	for _, id := range u.TenantTeamIDs {
+		teamIDs = append(teamIDs, json.Number(strconv.FormatUint(uint64(id), 10)))
+	}
+	return map[string]interface{}{
+		"tenant_team_ids": teamIDs,
+	}, nil
+}
+
 type UserTeam struct {
 	// Team is the team object.
 	Team
@@ -143,6 +177,9 @@ type UserListOptions struct {
 
 	// TeamID, if set, indicates to only return members of the identified team.
 	TeamID uint
+	// TenantID, if set, indicates to only return the users of the identified
+	// tenant.
+	TenantID *uint
 }
 
 // UserPayload is used to modify an existing user
@@ -160,6 +197,9 @@ type UserPayload struct {
 	APIOnly                  *bool       `json:"api_only,omitempty"`
 	Teams                    *[]UserTeam `json:"teams,omitempty"`
 	NewPassword              *string     `json:"new_password,omitempty"`
+	// TenantID is the tenant of the new user, it is set by the service from
+	// the creator of the user or the invite.
+	TenantID *uint `json:"-"`
 }
 
 func (p *UserPayload) VerifyInviteCreate() error {
@@ -301,6 +341,7 @@ func (p UserPayload) User(keySize, cost int) (*User, error) {
 	if p.GlobalRole != nil {
 		user.GlobalRole = p.GlobalRole
 	}
+	user.TenantID = p.TenantID
 
 	return user, nil
 }
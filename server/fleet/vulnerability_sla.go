@@ -0,0 +1,147 @@
+package fleet
+
+import (
+	"net/url"
+	"time"
+)
+
+// VulnerabilitySeverity is the severity of a vulnerability, derived from its
+// CVSS score as defined by the CVSS v3 qualitative severity rating scale.
+type VulnerabilitySeverity string
+
+const (
+	VulnerabilitySeverityCritical VulnerabilitySeverity = "critical"
+	VulnerabilitySeverityHigh     VulnerabilitySeverity = "high"
+	VulnerabilitySeverityMedium   VulnerabilitySeverity = "medium"
+	VulnerabilitySeverityLow      VulnerabilitySeverity = "low"
+)
+
+// VulnerabilitySeverities are the severities of the vulnerabilities, most
+// severe first.
+var VulnerabilitySeverities = []VulnerabilitySeverity{
+	VulnerabilitySeverityCritical,
+	VulnerabilitySeverityHigh,
+	VulnerabilitySeverityMedium,
+	VulnerabilitySeverityLow,
+}
+
+// MinCVSSScore returns the minimum CVSS score of the vulnerabilities of the
+// severity.
+func (s VulnerabilitySeverity) MinCVSSScore() float64 {
+	switch s {
+	case VulnerabilitySeverityCritical:
+		return 9
+	case VulnerabilitySeverityHigh:
+		return 7
+	case VulnerabilitySeverityMedium:
+		return 4
+	default:
+		// a score of 0 has no severity
+		return 0.1
+	}
+}
+
+// VulnerabilitySeverityFromCVSSScore returns the severity of a vulnerability
+// with the given CVSS score, or an empty severity if the score is 0.
+func VulnerabilitySeverityFromCVSSScore(score float64) VulnerabilitySeverity {
+	for _, s := range VulnerabilitySeverities {
+		if score >= s.MinCVSSScore() {
+			return s
+		}
+	}
+	return ""
+}
+
+// VulnerabilitySLASettings are the remediation SLAs of the vulnerabilities,
+// i.e. the number of days after which a vulnerability detected on a host is
+// overdue, per severity. A number of days of 0 means no SLA for the severity.
+// The vulnerabilities without a CVSS score have no SLA.
+type VulnerabilitySLASettings struct {
+	Enable       bool `json:"enable"`
+	CriticalDays int  `json:"critical_days"`
+	HighDays     int  `json:"high_days"`
+	MediumDays   int  `json:"medium_days"`
+	LowDays      int  `json:"low_days"`
+	// DestinationURL is the URL of the webhook that receives the
+	// vulnerabilities that became overdue, if set.
+	DestinationURL string `json:"destination_url"`
+}
+
+// Days returns the SLA of the severity, in days.
+func (s VulnerabilitySLASettings) Days(severity VulnerabilitySeverity) int {
+	switch severity {
+	case VulnerabilitySeverityCritical:
+		return s.CriticalDays
+	case VulnerabilitySeverityHigh:
+		return s.HighDays
+	case VulnerabilitySeverityMedium:
+		return s.MediumDays
+	case VulnerabilitySeverityLow:
+		return s.LowDays
+	}
+	return 0
+}
+
+// Validate validates the settings, errors are added to invalid with the keys
+// prefixed by prefix (e.g. "vulnerability_sla").
+func (s VulnerabilitySLASettings) Validate(prefix string, invalid *InvalidArgumentError) {
+	for _, d := range []struct {
+		key  string
+		days int
+	}{
+		{"critical_days", s.CriticalDays},
+		{"high_days", s.HighDays},
+		{"medium_days", s.MediumDays},
+		{"low_days", s.LowDays},
+	} {
+		if d.days < 0 {
+			invalid.Append(prefix+"."+d.key, "must be greater than or equal to 0")
+		}
+	}
+	if s.Enable && s.CriticalDays == 0 && s.HighDays == 0 && s.MediumDays == 0 && s.LowDays == 0 {
+		invalid.Append(prefix, "at least one SLA is required when enabled")
+	}
+	if s.DestinationURL != "" {
+		if u, err := url.ParseRequestURI(s.DestinationURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			invalid.Append(prefix+".destination_url", "must be a valid http or https URL")
+		}
+	}
+}
+
+// VulnerabilitySLABreachOptions are the options to list the vulnerabilities
+// that are overdue on the hosts of a team.
+type VulnerabilitySLABreachOptions struct {
+	// TeamID is the team of the hosts, nil for the hosts with no team.
+	TeamID *uint
+	SLA    VulnerabilitySLASettings
+	// Now is the time at which the SLAs are evaluated.
+	Now time.Time
+	// NotNotifiedOnly restricts the breaches to the hosts for which the breach
+	// was not escalated yet.
+	NotNotifiedOnly bool
+}
+
+// VulnerabilitySLABreach is a vulnerability that is overdue on some hosts.
+type VulnerabilitySLABreach struct {
+	CVE       string                `json:"cve" db:"cve"`
+	CVSSScore float64               `json:"cvss_score" db:"cvss_score"`
+	Severity  VulnerabilitySeverity `json:"severity" db:"-"`
+	SLADays   int                   `json:"sla_days" db:"-"`
+	// HostsCount is the number of hosts on which the vulnerability is overdue.
+	HostsCount uint `json:"hosts_count" db:"hosts_count"`
+	// FirstDetectedAt is when the vulnerability was first detected on one of
+	// the overdue hosts.
+	FirstDetectedAt time.Time `json:"first_detected_at" db:"first_detected_at"`
+}
+
+// VulnerabilitySLAReport is the remediation SLA status of the
+// vulnerabilities on the hosts of a team.
+type VulnerabilitySLAReport struct {
+	TeamID *uint                    `json:"team_id"`
+	SLA    VulnerabilitySLASettings `json:"sla"`
+	// OverdueCounts is the number of overdue vulnerabilities (counted once per
+	// host) by severity.
+	OverdueCounts map[VulnerabilitySeverity]uint `json:"overdue_counts"`
+	// Vulnerabilities are the overdue vulnerabilities, most severe first.
+	Vulnerabilities []*VulnerabilitySLABreach `json:"vulnerabilities"`
+}
@@ -0,0 +1,52 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilitySeverityFromCVSSScore(t *testing.T) {
+	for _, tc := range []struct {
+		score float64
+		want  VulnerabilitySeverity
+	}{
+		{0, ""},
+		{0.1, VulnerabilitySeverityLow},
+		{3.9, VulnerabilitySeverityLow},
+		{4, VulnerabilitySeverityMedium},
+		{6.9, VulnerabilitySeverityMedium},
+		{7, VulnerabilitySeverityHigh},
+		{8.9, VulnerabilitySeverityHigh},
+		{9, VulnerabilitySeverityCritical},
+		{10, VulnerabilitySeverityCritical},
+	} {
+		assert.Equal(t, tc.want, VulnerabilitySeverityFromCVSSScore(tc.score), "score %v", tc.score)
+	}
+}
+
+func TestVulnerabilitySLASettingsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		sla     VulnerabilitySLASettings
+		wantErr string
+	}{
+		{"disabled", VulnerabilitySLASettings{}, ""},
+		{"enabled", VulnerabilitySLASettings{Enable: true, CriticalDays: 14, DestinationURL: "https://example.com/sla"}, ""},
+		{"enabled without SLA", VulnerabilitySLASettings{Enable: true}, "vulnerability_sla at least one SLA is required when enabled"},
+		{"negative days", VulnerabilitySLASettings{HighDays: -1}, "vulnerability_sla.high_days must be greater than or equal to 0"},
+		{"invalid URL", VulnerabilitySLASettings{DestinationURL: "ftp://example.com"}, "vulnerability_sla.destination_url must be a valid http or https URL"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			invalid := &InvalidArgumentError{}
+			tc.sla.Validate("vulnerability_sla", invalid)
+			if tc.wantErr == "" {
+				require.False(t, invalid.HasErrors(), invalid.Error())
+				return
+			}
+			require.True(t, invalid.HasErrors())
+			require.Contains(t, invalid.Error(), tc.wantErr)
+		})
+	}
+}
@@ -32,13 +32,25 @@ type MDMWindowsBitLockerSummary struct {
 type MDMWindowsConfigProfile struct {
 	// ProfileUUID is the unique identifier of the configuration profile in
 	// Fleet. For Windows profiles, it is the letter "w" followed by a uuid.
-	ProfileUUID string                      `db:"profile_uuid" json:"profile_uuid"`
-	TeamID      *uint                       `db:"team_id" json:"team_id"`
-	Name        string                      `db:"name" json:"name"`
-	SyncML      []byte                      `db:"syncml" json:"-"`
-	Labels      []ConfigurationProfileLabel `db:"labels" json:"labels,omitempty"`
-	CreatedAt   time.Time                   `db:"created_at" json:"created_at"`
-	UploadedAt  time.Time                   `db:"uploaded_at" json:"updated_at"` // NOTE: JSON field is still `updated_at` for historical reasons, would be an API breaking change
+	ProfileUUID string `db:"profile_uuid" json:"profile_uuid"`
+	TeamID      *uint  `db:"team_id" json:"team_id"`
+	Name        string `db:"name" json:"name"`
+	SyncML      []byte `db:"syncml" json:"-"`
+	// Labels are the labels that the hosts must all be members of to receive
+	// the profile.
+	Labels []ConfigurationProfileLabel `db:"labels" json:"labels,omitempty"`
+	// LabelsIncludeAny are the labels that the hosts must be a member of at
+	// least one of to receive the profile.
+	LabelsIncludeAny []ConfigurationProfileLabel `db:"-" json:"labels_include_any,omitempty"`
+	// LabelsExcludeAny are the labels that the hosts must not be a member of
+	// to receive the profile.
+	LabelsExcludeAny []ConfigurationProfileLabel `db:"-" json:"labels_exclude_any,omitempty"`
+	// Rollout are the staged rollout settings to apply when the profile is
+	// created or its contents change, nil to deliver it to all the targeted
+	// hosts at once.
+	Rollout    *MDMProfileRolloutSettings `db:"-" json:"-"`
+	CreatedAt  time.Time                  `db:"created_at" json:"created_at"`
+	UploadedAt time.Time                  `db:"uploaded_at" json:"updated_at"` // NOTE: JSON field is still `updated_at` for historical reasons, would be an API breaking change
 }
 
 // ValidateUserProvided ensures that the SyncML content in the profile is valid
@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// AdminNotificationsDigestMailer is the daily email digest of the unread
+// admin notifications.
+type AdminNotificationsDigestMailer struct {
+	BaseURL       template.URL
+	AssetURL      template.URL
+	Notifications []*fleet.AdminNotification
+	CurrentYear   int
+}
+
+func (m *AdminNotificationsDigestMailer) Message() ([]byte, error) {
+	m.CurrentYear = time.Now().Year()
+	t, err := server.GetTemplate("server/mail/templates/admin_notifications_digest.html", "email_template")
+	if err != nil {
+		return nil, err
+	}
+	var msg bytes.Buffer
+	if err := t.Execute(&msg, m); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
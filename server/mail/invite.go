@@ -1,11 +1,9 @@
 package mail
 
 import (
-	"bytes"
 	"html/template"
 	"time"
 
-	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
@@ -17,18 +15,12 @@ type InviteMailer struct {
 	InvitedBy   string
 	OrgName     string
 	CurrentYear int
+	// CustomTemplate is the customized HTML template of the email, the
+	// default template is used if empty.
+	CustomTemplate string
 }
 
 func (i *InviteMailer) Message() ([]byte, error) {
 	i.CurrentYear = time.Now().Year()
-	t, err := server.GetTemplate("server/mail/templates/invite_token.html", "email_template")
-	if err != nil {
-		return nil, err
-	}
-
-	var msg bytes.Buffer
-	if err = t.Execute(&msg, i); err != nil {
-		return nil, err
-	}
-	return msg.Bytes(), nil
+	return renderTemplate(fleet.EmailTemplateInvite, i.CustomTemplate, i)
 }
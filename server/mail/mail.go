@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"html/template"
 	"net"
+	netmail "net/mail"
 	"net/smtp"
 	"strings"
 	"time"
@@ -75,7 +76,16 @@ func getMessageBody(e fleet.Email, f fromFunc) ([]byte, error) {
 }
 
 func getFrom(e fleet.Email) (string, error) {
-	return "From: " + e.SMTPSettings.SMTPSenderAddress + "\r\n", nil
+	return "From: " + formatFrom(e.SenderName, e.FromAddress()) + "\r\n", nil
+}
+
+// formatFrom returns the value of the From header for the address, with the
+// display name if set.
+func formatFrom(name, address string) string {
+	if name == "" {
+		return address
+	}
+	return (&netmail.Address{Name: name, Address: address}).String()
 }
 
 func (m mailService) SendEmail(e fleet.Email) error {
@@ -164,7 +174,7 @@ func (m mailService) sendMail(e fleet.Email, msg []byte) error {
 	}
 
 	if e.SMTPSettings.SMTPAuthenticationMethod == fleet.AuthMethodNameCramMD5 {
-		err = smtp.SendMail(smtpHost, auth, e.SMTPSettings.SMTPSenderAddress, e.To, msg)
+		err = smtp.SendMail(smtpHost, auth, e.FromAddress(), e.To, msg)
 		if err != nil {
 			return fmt.Errorf("failed to send mail. crammd5 auth method: %w", err)
 		}
@@ -199,7 +209,7 @@ func (m mailService) sendMail(e fleet.Email, msg []byte) error {
 			return fmt.Errorf("client auth error: %w", err)
 		}
 	}
-	if err = client.Mail(e.SMTPSettings.SMTPSenderAddress); err != nil {
+	if err = client.Mail(e.FromAddress()); err != nil {
 		return fmt.Errorf("could not issue mail to provided address: %w", err)
 	}
 	for _, recip := range e.To {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -196,6 +197,20 @@ func Test_getFrom(t *testing.T) {
 			want:    "From: foo@bar.com\r\n",
 			wantErr: assert.NoError,
 		},
+		{
+			name: "should use the sender identity of the email template",
+			args: args{
+				e: fleet.Email{
+					SMTPSettings: fleet.SMTPSettings{
+						SMTPSenderAddress: "foo@bar.com",
+					},
+					SenderName:    "Acme IT",
+					SenderAddress: "it@acme.com",
+				},
+			},
+			want:    "From: \"Acme IT\" <it@acme.com>\r\n",
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -207,3 +222,44 @@ func Test_getFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTemplate(t *testing.T) {
+	cases := []struct {
+		desc    string
+		name    fleet.EmailTemplateName
+		body    string
+		wantErr string
+	}{
+		{"valid invite", fleet.EmailTemplateInvite, `<a href="{{.BaseURL}}/login/invites/{{.Token}}">Join {{.OrgName}}</a>`, ""},
+		{"valid with conditions", fleet.EmailTemplateInvite, `{{if .SSOEnabled}}{{$.Token}}{{else}}{{.Token}} {{.Name | html}}{{end}}`, ""},
+		{"valid migration", fleet.EmailTemplateMDMMigration, `<p>{{.OrgName}} migrates {{.HostDisplayName}}</p>`, ""},
+		{"valid lock/wipe notification", fleet.EmailTemplateLockWipeNotification, `<p>{{.OrgName}} {{.Action}} {{.HostDisplayName}}{{if eq .Action "wiped"}}, sorry{{end}}</p>`, ""},
+		{"missing lock/wipe action", fleet.EmailTemplateLockWipeNotification, `<p>{{.OrgName}} did something to {{.HostDisplayName}}</p>`, "must use the {{.Action}} variable"},
+		{"invalid syntax", fleet.EmailTemplateInvite, `{{.Token`, "invalid template"},
+		{"unknown variable", fleet.EmailTemplatePasswordReset, `{{.Token}} {{.OrgName}}`, "unknown variable {{.OrgName}}"},
+		{"unknown variable in else", fleet.EmailTemplateInvite, `{{if .SSOEnabled}}{{.Token}}{{else}}{{.Position}}{{end}}`, "unknown variable {{.Position}}"},
+		{"missing required variable", fleet.EmailTemplatePasswordReset, `<p>Reset your password at {{.BaseURL}}</p>`, "must use the {{.Token}} variable"},
+		{"unknown template", fleet.EmailTemplateName("foo"), `{{.Token}}`, "unknown email template"},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := ValidateTemplate(c.name, c.body)
+			if c.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	mailer := MDMMigrationMailer{
+		OrgName:         "Acme",
+		HostDisplayName: "<b>host</b>",
+		CustomTemplate:  `<p>{{.OrgName}} migrates {{.HostDisplayName}} ({{.CurrentYear}})</p>`,
+	}
+	out, err := mailer.Message()
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("<p>Acme migrates &lt;b&gt;host&lt;/b&gt; (%d)</p>", time.Now().Year()), string(out))
+}
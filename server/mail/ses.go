@@ -27,7 +27,11 @@ func getFromSES(e fleet.Email) (string, error) {
 	if err != nil || len(serverURL.Host) == 0 {
 		return "", fmt.Errorf("failed to parse server url %s err: %w", e.ServerURL, err)
 	}
-	return fmt.Sprintf("From: %s\r\n", fmt.Sprintf("do-not-reply@%s", serverURL.Host)), nil
+	address := e.SenderAddress
+	if address == "" {
+		address = fmt.Sprintf("do-not-reply@%s", serverURL.Host)
+	}
+	return fmt.Sprintf("From: %s\r\n", formatFrom(e.SenderName, address)), nil
 }
 
 func (s *sesSender) SendEmail(e fleet.Email) error {
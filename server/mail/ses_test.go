@@ -28,6 +28,16 @@ func Test_getFromSES(t *testing.T) {
 			want:    "From: do-not-reply@foobar.fleetdm.com\r\n",
 			wantErr: assert.NoError,
 		},
+		{
+			name: "should use the sender identity of the email template",
+			args: args{e: fleet.Email{
+				ServerURL:     "https://foobar.fleetdm.com",
+				SenderName:    "Acme IT",
+				SenderAddress: "it@acme.com",
+			}},
+			want:    "From: \"Acme IT\" <it@acme.com>\r\n",
+			wantErr: assert.NoError,
+		},
 		{
 			name: "should error when we fail to parse fleet server url",
 			args: args{e: fleet.Email{
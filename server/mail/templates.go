@@ -0,0 +1,290 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"text/template/parse"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/bindata"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// AssetURL is the URL of the image assets used by the email templates.
+const AssetURL template.URL = "https://fleetdm.com/images/permanent"
+
+// emailTemplates describes the compiled-in template of the emails that can be
+// customized, along with the variables available to them.
+var emailTemplates = map[fleet.EmailTemplateName]struct {
+	path string
+	// variables are the fields of the mailer that can be used in the template.
+	variables []string
+	// required are the variables that must be used by a customized template,
+	// the email is useless without them.
+	required []string
+}{
+	fleet.EmailTemplateInvite: {
+		path:      "server/mail/templates/invite_token.html",
+		variables: []string{"AssetURL", "BaseURL", "CurrentYear", "Email", "InvitedBy", "Name", "OrgName", "SSOEnabled", "Token"},
+		required:  []string{"Token"},
+	},
+	fleet.EmailTemplatePasswordReset: {
+		path:      "server/mail/templates/password_reset.html",
+		variables: []string{"AssetURL", "BaseURL", "CurrentYear", "Token"},
+		required:  []string{"Token"},
+	},
+	fleet.EmailTemplateMDMMigration: {
+		path:      "server/mail/templates/mdm_migration.html",
+		variables: []string{"AssetURL", "BaseURL", "ContactURL", "CurrentYear", "HostDisplayName", "OrgName"},
+	},
+	fleet.EmailTemplateLockWipeNotification: {
+		path:      "server/mail/templates/lock_wipe_notification.html",
+		variables: []string{"Action", "AssetURL", "BaseURL", "ContactURL", "CurrentYear", "HostDisplayName", "OrgName"},
+		required:  []string{"Action"},
+	},
+}
+
+// renderTemplate renders the email with the customized template if set, or
+// with its compiled-in template otherwise.
+func renderTemplate(name fleet.EmailTemplateName, customTemplate string, data any) ([]byte, error) {
+	body := customTemplate
+	if body == "" {
+		b, err := DefaultTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	t, err := template.New("email_template").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	var msg bytes.Buffer
+	if err := t.Execute(&msg, data); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// DefaultTemplate returns the compiled-in HTML template of the email.
+func DefaultTemplate(name fleet.EmailTemplateName) (string, error) {
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown email template %q", name)
+	}
+	b, err := bindata.Asset(tmpl.path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// TemplateVariables returns the names of the variables available to the
+// template of the email.
+func TemplateVariables(name fleet.EmailTemplateName) []string {
+	return emailTemplates[name].variables
+}
+
+// ValidateTemplate checks that body is a valid HTML template for the email:
+// it must only use the variables available to the email (and use the
+// required ones) and render successfully.
+func ValidateTemplate(name fleet.EmailTemplateName, body string) error {
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return fmt.Errorf("unknown email template %q", name)
+	}
+
+	t, err := template.New("email_template").Parse(body)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	used := make(map[string]bool)
+	if t.Tree != nil {
+		collectTemplateVariables(t.Tree.Root, used)
+	}
+	allowed := make(map[string]bool, len(tmpl.variables))
+	for _, v := range tmpl.variables {
+		allowed[v] = true
+	}
+	for v := range used {
+		if !allowed[v] {
+			return fmt.Errorf("unknown variable {{.%s}}", v)
+		}
+	}
+	for _, v := range tmpl.required {
+		if !used[v] {
+			return fmt.Errorf("must use the {{.%s}} variable", v)
+		}
+	}
+
+	mailer, err := SampleMailer(name, body, SampleData{})
+	if err != nil {
+		return err
+	}
+	if err := t.Execute(io.Discard, mailer); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	return nil
+}
+
+// collectTemplateVariables adds to vars the names of the top-level fields
+// referenced by the template node. The fields referenced inside range and
+// with blocks are relative to another value and are not collected, only
+// their pipeline is.
+func collectTemplateVariables(node parse.Node, vars map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectTemplateVariables(c, vars)
+		}
+	case *parse.ActionNode:
+		collectTemplateVariables(n.Pipe, vars)
+	case *parse.IfNode:
+		collectTemplateVariables(n.Pipe, vars)
+		collectTemplateVariables(n.List, vars)
+		collectTemplateVariables(n.ElseList, vars)
+	case *parse.RangeNode:
+		collectTemplateVariables(n.Pipe, vars)
+		collectTemplateVariables(n.ElseList, vars)
+	case *parse.WithNode:
+		collectTemplateVariables(n.Pipe, vars)
+		collectTemplateVariables(n.ElseList, vars)
+	case *parse.TemplateNode:
+		collectTemplateVariables(n.Pipe, vars)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateVariables(cmd, vars)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateVariables(arg, vars)
+		}
+	case *parse.FieldNode:
+		vars[n.Ident[0]] = true
+	case *parse.VariableNode:
+		// $.Field refers to a top-level field
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			vars[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		collectTemplateVariables(n.Node, vars)
+	}
+}
+
+// SampleData is the data of the organization used to render sample emails.
+type SampleData struct {
+	BaseURL    template.URL
+	AssetURL   template.URL
+	OrgName    string
+	ContactURL string
+	// UserName is the name of the user that previews the email.
+	UserName string
+}
+
+// SampleMailer returns a mailer for the email that renders the template with
+// sample data, it is used to validate and preview customized templates.
+func SampleMailer(name fleet.EmailTemplateName, customTemplate string, data SampleData) (fleet.Mailer, error) {
+	const sampleToken = "c2FtcGxlLXRva2Vu"
+
+	switch name {
+	case fleet.EmailTemplateInvite:
+		return &InviteMailer{
+			Invite: &fleet.Invite{
+				Email: "jane.doe@example.com",
+				Name:  "Jane Doe",
+				Token: sampleToken,
+			},
+			BaseURL:        data.BaseURL,
+			AssetURL:       data.AssetURL,
+			InvitedBy:      data.UserName,
+			OrgName:        data.OrgName,
+			CustomTemplate: customTemplate,
+		}, nil
+	case fleet.EmailTemplatePasswordReset:
+		return &PasswordResetMailer{
+			BaseURL:        data.BaseURL,
+			AssetURL:       data.AssetURL,
+			Token:          sampleToken,
+			CustomTemplate: customTemplate,
+		}, nil
+	case fleet.EmailTemplateMDMMigration:
+		return &MDMMigrationMailer{
+			BaseURL:         data.BaseURL,
+			AssetURL:        data.AssetURL,
+			OrgName:         data.OrgName,
+			ContactURL:      data.ContactURL,
+			HostDisplayName: "Jane's MacBook Pro",
+			CustomTemplate:  customTemplate,
+		}, nil
+	case fleet.EmailTemplateLockWipeNotification:
+		return &LockWipeNotificationMailer{
+			BaseURL:         data.BaseURL,
+			AssetURL:        data.AssetURL,
+			OrgName:         data.OrgName,
+			ContactURL:      data.ContactURL,
+			HostDisplayName: "Jane's MacBook Pro",
+			Action:          LockWipeActionLocked,
+			CustomTemplate:  customTemplate,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+}
+
+// MDMMigrationMailer is used to build the email sent to the end user of a
+// host when the migration of the host from its current MDM solution to Fleet
+// is triggered.
+type MDMMigrationMailer struct {
+	BaseURL         template.URL
+	AssetURL        template.URL
+	OrgName         string
+	ContactURL      string
+	HostDisplayName string
+	CurrentYear     int
+	// CustomTemplate is the customized HTML template of the email, the
+	// default template is used if empty.
+	CustomTemplate string
+}
+
+func (m *MDMMigrationMailer) Message() ([]byte, error) {
+	m.CurrentYear = time.Now().Year()
+	return renderTemplate(fleet.EmailTemplateMDMMigration, m.CustomTemplate, m)
+}
+
+// The actions of the lock and wipe notification email.
+const (
+	LockWipeActionLocked   = "locked"
+	LockWipeActionUnlocked = "unlocked"
+	LockWipeActionWiped    = "wiped"
+)
+
+// LockWipeNotificationMailer is used to build the email sent to the end user
+// of a host when the host is locked, unlocked or wiped.
+type LockWipeNotificationMailer struct {
+	BaseURL         template.URL
+	AssetURL        template.URL
+	OrgName         string
+	ContactURL      string
+	HostDisplayName string
+	// Action is the action taken on the host: "locked", "unlocked" or "wiped".
+	Action      string
+	CurrentYear int
+	// CustomTemplate is the customized HTML template of the email, the
+	// default template is used if empty.
+	CustomTemplate string
+}
+
+func (m *LockWipeNotificationMailer) Message() ([]byte, error) {
+	m.CurrentYear = time.Now().Year()
+	return renderTemplate(fleet.EmailTemplateLockWipeNotification, m.CustomTemplate, m)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
 type ChangeEmailMailer struct {
@@ -38,18 +39,12 @@ type PasswordResetMailer struct {
 	Token string
 	// Current year for copyright year
 	CurrentYear int
+	// CustomTemplate is the customized HTML template of the email, the
+	// default template is used if empty.
+	CustomTemplate string
 }
 
 func (r PasswordResetMailer) Message() ([]byte, error) {
 	r.CurrentYear = time.Now().Year()
-	t, err := server.GetTemplate("server/mail/templates/password_reset.html", "email_template")
-	if err != nil {
-		return nil, err
-	}
-
-	var msg bytes.Buffer
-	if err = t.Execute(&msg, r); err != nil {
-		return nil, err
-	}
-	return msg.Bytes(), nil
+	return renderTemplate(fleet.EmailTemplatePasswordReset, r.CustomTemplate, r)
 }
@@ -2,10 +2,12 @@ package apple_mdm
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/fleetdm/fleet/v4/pkg/retry"
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -13,15 +15,8 @@ import (
 	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
 	nanomdm_push "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push"
-	"github.com/groob/plist"
 )
 
-// commandPayload is the common structure all MDM commands use
-type commandPayload struct {
-	CommandUUID string
-	Command     any
-}
-
 // MDMAppleCommander contains methods to enqueue commands managed by Fleet and
 // send push notifications to hosts.
 //
@@ -32,14 +27,29 @@ type MDMAppleCommander struct {
 	storage fleet.MDMAppleStore
 	config  config.MDMConfig
 	pusher  nanomdm_push.Pusher
+
+	// chunkSize is the maximum number of hosts a command is enqueued for at
+	// once by EnqueueCommandInChunks.
+	chunkSize int
+	// chunkRetryInterval is the initial interval between the attempts to
+	// enqueue a chunk, it is doubled after each attempt.
+	chunkRetryInterval time.Duration
 }
 
+const (
+	defaultEnqueueChunkSize          = 1000
+	defaultEnqueueChunkRetryInterval = 500 * time.Millisecond
+	enqueueChunkMaxAttempts          = 3
+)
+
 // NewMDMAppleCommander creates a new commander instance.
 func NewMDMAppleCommander(mdmStorage fleet.MDMAppleStore, mdmPushService nanomdm_push.Pusher, config config.MDMConfig) *MDMAppleCommander {
 	return &MDMAppleCommander{
-		storage: mdmStorage,
-		pusher:  mdmPushService,
-		config:  config,
+		storage:            mdmStorage,
+		pusher:             mdmPushService,
+		config:             config,
+		chunkSize:          defaultEnqueueChunkSize,
+		chunkRetryInterval: defaultEnqueueChunkRetryInterval,
 	}
 }
 
@@ -51,64 +61,39 @@ func (svc *MDMAppleCommander) InstallProfile(ctx context.Context, hostUUIDs []st
 		return ctxerr.Wrap(ctx, err, "signing profile")
 	}
 
-	base64Profile := base64.StdEncoding.EncodeToString(signedProfile)
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-	<key>CommandUUID</key>
-	<string>%s</string>
-	<key>Command</key>
-	<dict>
-		<key>RequestType</key>
-		<string>InstallProfile</string>
-		<key>Payload</key>
-		<data>%s</data>
-	</dict>
-</dict>
-</plist>`, uuid, base64Profile)
+	raw, err := marshalCommand(uuid, installProfilePayload{
+		RequestType: "InstallProfile",
+		Payload:     signedProfile,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal install profile command")
+	}
 	err = svc.EnqueueCommand(ctx, hostUUIDs, raw)
 	return ctxerr.Wrap(ctx, err, "commander install profile")
 }
 
 // InstallProfile sends the homonymous MDM command to the given hosts.
 func (svc *MDMAppleCommander) RemoveProfile(ctx context.Context, hostUUIDs []string, profileIdentifier string, uuid string) error {
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-	<key>CommandUUID</key>
-	<string>%s</string>
-	<key>Command</key>
-	<dict>
-		<key>RequestType</key>
-		<string>RemoveProfile</string>
-		<key>Identifier</key>
-		<string>%s</string>
-	</dict>
-</dict>
-</plist>`, uuid, profileIdentifier)
-	err := svc.EnqueueCommand(ctx, hostUUIDs, raw)
+	raw, err := marshalCommand(uuid, removeProfilePayload{
+		RequestType: "RemoveProfile",
+		Identifier:  profileIdentifier,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal remove profile command")
+	}
+	err = svc.EnqueueCommand(ctx, hostUUIDs, raw)
 	return ctxerr.Wrap(ctx, err, "commander remove profile")
 }
 
 func (svc *MDMAppleCommander) DeviceLock(ctx context.Context, host *fleet.Host, uuid string) error {
 	pin := GenerateRandomPin(6)
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-  <dict>
-    <key>CommandUUID</key>
-    <string>%s</string>
-    <key>Command</key>
-    <dict>
-      <key>RequestType</key>
-      <string>DeviceLock</string>
-      <key>PIN</key>
-      <string>%s</string>
-    </dict>
-  </dict>
-</plist>`, uuid, pin)
+	raw, err := marshalCommand(uuid, deviceLockPayload{
+		RequestType: "DeviceLock",
+		PIN:         pin,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal device lock command")
+	}
 
 	cmd, err := mdm.DecodeCommand([]byte(raw))
 	if err != nil {
@@ -119,32 +104,40 @@ func (svc *MDMAppleCommander) DeviceLock(ctx context.Context, host *fleet.Host,
 		return ctxerr.Wrap(ctx, err, "enqueuing for DeviceLock")
 	}
 
-	if err := svc.sendNotifications(ctx, []string{host.UUID}); err != nil {
+	if err := svc.SendNotifications(ctx, []string{host.UUID}); err != nil {
 		return ctxerr.Wrap(ctx, err, "sending notifications for DeviceLock")
 	}
 
 	return nil
 }
 
-func (svc *MDMAppleCommander) EraseDevice(ctx context.Context, host *fleet.Host, uuid string) error {
-	pin := GenerateRandomPin(6)
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-  <dict>
-    <key>CommandUUID</key>
-    <string>%s</string>
-    <key>Command</key>
-    <dict>
-      <key>RequestType</key>
-      <string>EraseDevice</string>
-      <key>PIN</key>
-      <string>%s</string>
-      <key>ObliterationBehavior</key>
-      <string>Default</string>
-    </dict>
-  </dict>
-</plist>`, uuid, pin)
+// EraseDevice sends the EraseDevice command to the host, with a random Find
+// My PIN unless opts.SkipPIN is set.
+func (svc *MDMAppleCommander) EraseDevice(ctx context.Context, host *fleet.Host, uuid string, opts fleet.MDMAppleEraseDeviceOptions) error {
+	if err := opts.Validate(); err != nil {
+		return ctxerr.Wrap(ctx, err, "validate erase device options")
+	}
+
+	var pin string
+	if !opts.SkipPIN {
+		pinLength := opts.PINLength
+		if pinLength == 0 {
+			pinLength = fleet.MDMAppleEraseDeviceDefaultPINLength
+		}
+		pin = GenerateRandomPin(pinLength)
+	}
+	obliterationBehavior := opts.ObliterationBehavior
+	if obliterationBehavior == "" {
+		obliterationBehavior = fleet.MDMAppleObliterationBehaviorDefault
+	}
+	raw, err := marshalCommand(uuid, eraseDevicePayload{
+		RequestType:          "EraseDevice",
+		PIN:                  pin,
+		ObliterationBehavior: obliterationBehavior,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal erase device command")
+	}
 
 	cmd, err := mdm.DecodeCommand([]byte(raw))
 	if err != nil {
@@ -155,7 +148,7 @@ func (svc *MDMAppleCommander) EraseDevice(ctx context.Context, host *fleet.Host,
 		return ctxerr.Wrap(ctx, err, "enqueuing for DeviceWipe")
 	}
 
-	if err := svc.sendNotifications(ctx, []string{host.UUID}); err != nil {
+	if err := svc.SendNotifications(ctx, []string{host.UUID}); err != nil {
 		return ctxerr.Wrap(ctx, err, "sending notifications for DeviceWipe")
 	}
 
@@ -163,73 +156,389 @@ func (svc *MDMAppleCommander) EraseDevice(ctx context.Context, host *fleet.Host,
 }
 
 func (svc *MDMAppleCommander) InstallEnterpriseApplication(ctx context.Context, hostUUIDs []string, uuid string, manifestURL string) error {
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-  <dict>
-    <key>Command</key>
-    <dict>
-      <key>ManifestURL</key>
-      <string>%s</string>
-      <key>RequestType</key>
-      <string>InstallEnterpriseApplication</string>
-    </dict>
-
-    <key>CommandUUID</key>
-    <string>%s</string>
-  </dict>
-</plist>`, manifestURL, uuid)
+	raw, err := marshalCommand(uuid, installEnterpriseApplicationPayload{
+		RequestType: "InstallEnterpriseApplication",
+		ManifestURL: manifestURL,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal install enterprise application command")
+	}
 	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
 }
 
-type installEnterpriseApplicationPayload struct {
-	Manifest    *appmanifest.Manifest
-	RequestType string
-}
-
 func (svc *MDMAppleCommander) InstallEnterpriseApplicationWithEmbeddedManifest(
 	ctx context.Context,
 	hostUUIDs []string,
 	uuid string,
 	manifest *appmanifest.Manifest,
 ) error {
-	cmd := commandPayload{
-		CommandUUID: uuid,
-		Command: installEnterpriseApplicationPayload{
-			RequestType: "InstallEnterpriseApplication",
-			Manifest:    manifest,
-		},
+	raw, err := marshalCommand(uuid, installEnterpriseApplicationPayload{
+		RequestType: "InstallEnterpriseApplication",
+		Manifest:    manifest,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal install enterprise application command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// InstallApplicationOptions are the options of the InstallApplication
+// command.
+type InstallApplicationOptions struct {
+	// Managed installs the app as a managed app, which is removed when the
+	// device unenrolls from MDM.
+	Managed bool
+	// Removable is whether the user can remove the app. It requires iOS and
+	// iPadOS 14 or later, the app cannot be removed on the supervised devices
+	// of the older versions.
+	Removable bool
+}
+
+// InstallApplication sends the homonym [command][1] to the devices to install
+// the App Store app with the given iTunes Store ID, whose license is assigned
+// to the devices via VPP. The devices often respond with NotNow while they are
+// locked or busy, see RetryNotNowInstallApplicationCommands in the service
+// package.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/install_application
+func (svc *MDMAppleCommander) InstallApplication(ctx context.Context, hostUUIDs []string, uuid string, iTunesStoreID int, opts InstallApplicationOptions) error {
+	payload := installApplicationPayload{
+		RequestType:   "InstallApplication",
+		ITunesStoreID: iTunesStoreID,
+		Options:       installApplicationOptions{PurchaseMethod: 1},
+		Attributes:    installApplicationAttributes{Removable: opts.Removable},
+	}
+	if opts.Managed {
+		payload.ManagementFlags = 1
+		payload.InstallAsManaged = true
+	} else {
+		payload.Options.NotManaged = true
+	}
+	raw, err := marshalCommand(uuid, payload)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal install application command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// RemoveApplication sends the homonym [command][1] to the devices to remove
+// the managed app with the given bundle identifier. The app is removed from
+// the software inventory of the hosts when they acknowledge the command.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/remove_application
+func (svc *MDMAppleCommander) RemoveApplication(ctx context.Context, hostUUIDs []string, uuid string, identifier string) error {
+	raw, err := marshalCommand(uuid, removeApplicationPayload{
+		RequestType: "RemoveApplication",
+		Identifier:  identifier,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal remove application command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// EnableLostMode sends the homonym [command][1] to the supervised iOS and
+// iPadOS devices to lock them and display the given message and phone number
+// on the lock screen.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/enable_lost_mode
+func (svc *MDMAppleCommander) EnableLostMode(ctx context.Context, hostUUIDs []string, uuid string, settings fleet.HostLostModeSettings) error {
+	raw, err := marshalCommand(uuid, enableLostModePayload{
+		RequestType: "EnableLostMode",
+		Message:     settings.Message,
+		PhoneNumber: settings.PhoneNumber,
+		Footnote:    settings.Footnote,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal enable lost mode command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// DisableLostMode sends the homonym [command][1] to the devices to take them
+// out of Lost Mode.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/disable_lost_mode
+func (svc *MDMAppleCommander) DisableLostMode(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "DisableLostMode"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal disable lost mode command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// DeviceLocation sends the homonym [command][1] to the devices in Lost Mode
+// to get their location, the devices not in Lost Mode respond with an error.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/device_location
+func (svc *MDMAppleCommander) DeviceLocation(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "DeviceLocation"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal device location command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// ActivationLockBypassCode sends the homonym [command][1] to the supervised
+// devices to escrow the code that bypasses their Activation Lock, see
+// MDMAppleCheckinAndCommandService.CommandAndReportResults for how the
+// response is stored.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/activation_lock_bypass_code
+func (svc *MDMAppleCommander) ActivationLockBypassCode(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "ActivationLockBypassCode"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal activation lock bypass code command")
 	}
 
-	raw, err := plist.Marshal(cmd)
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// ClearActivationLockBypassCode sends the homonym [command][1] to the devices
+// to clear the Activation Lock bypass code they escrowed.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/clear_activation_lock_bypass_code
+func (svc *MDMAppleCommander) ClearActivationLockBypassCode(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "ClearActivationLockBypassCode"})
 	if err != nil {
-		return fmt.Errorf("marshal command payload plist: %w", err)
+		return ctxerr.Wrap(ctx, err, "marshal clear activation lock bypass code command")
 	}
 
-	return svc.EnqueueCommand(ctx, hostUUIDs, string(raw))
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
 }
 
-func (svc *MDMAppleCommander) AccountConfiguration(ctx context.Context, hostUUIDs []string, uuid, fullName, userName string) error {
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-  <dict>
-    <key>Command</key>
-    <dict>
-      <key>PrimaryAccountFullName</key>
-      <string>%s</string>
-      <key>PrimaryAccountUserName</key>
-      <string>%s</string>
-      <key>LockPrimaryAccountInfo</key>
-      <true />
-      <key>RequestType</key>
-      <string>AccountConfiguration</string>
-    </dict>
+// SetRecoveryLock sends the homonym [command][1] to the Apple silicon Macs to
+// replace their Recovery Lock password currentPassword (empty if they have
+// none) by newPassword, see RotateAppleRecoveryLockPasswords in the service
+// package for how the passwords are escrowed.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/set_recovery_lock
+func (svc *MDMAppleCommander) SetRecoveryLock(ctx context.Context, hostUUIDs []string, uuid, currentPassword, newPassword string) error {
+	raw, err := marshalCommand(uuid, setRecoveryLockPayload{
+		RequestType:     "SetRecoveryLock",
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal set recovery lock command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
 
-    <key>CommandUUID</key>
-    <string>%s</string>
-  </dict>
-</plist>`, fullName, userName, uuid)
+// VerifyRecoveryLock sends the homonym [command][1] to the Apple silicon Macs
+// to check that password is their Recovery Lock password.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/verify_recovery_lock
+func (svc *MDMAppleCommander) VerifyRecoveryLock(ctx context.Context, hostUUIDs []string, uuid, password string) error {
+	raw, err := marshalCommand(uuid, verifyRecoveryLockPayload{
+		RequestType: "VerifyRecoveryLock",
+		Password:    password,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal verify recovery lock command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// SetFirmwarePassword sends the homonym [command][1] to the Intel Mac to
+// replace its EFI firmware password currentPassword (empty if it has none) by
+// a new random password. Like the PIN of the DeviceLock command, the new
+// password is generated here and escrowed with the command, it can only be
+// called for a single host so that each host gets its own password. The new
+// password takes effect when the host restarts.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/set_firmware_password
+func (svc *MDMAppleCommander) SetFirmwarePassword(ctx context.Context, host *fleet.Host, uuid, currentPassword string) error {
+	newPassword, err := GenerateFirmwarePassword()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "generate firmware password")
+	}
+	raw, err := marshalCommand(uuid, setFirmwarePasswordPayload{
+		RequestType:     "SetFirmwarePassword",
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal set firmware password command")
+	}
+
+	cmd, err := mdm.DecodeCommand([]byte(raw))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "decoding command")
+	}
+
+	if err := svc.storage.EnqueueSetFirmwarePasswordCommand(ctx, host, cmd, newPassword); err != nil {
+		return ctxerr.Wrap(ctx, err, "enqueuing for SetFirmwarePassword")
+	}
+
+	if err := svc.SendNotifications(ctx, []string{host.UUID}); err != nil {
+		return ctxerr.Wrap(ctx, err, "sending notifications for SetFirmwarePassword")
+	}
+
+	return nil
+}
+
+// VerifyFirmwarePassword sends the homonym [command][1] to the Intel Macs to
+// check that password is their EFI firmware password.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/verify_firmware_password
+func (svc *MDMAppleCommander) VerifyFirmwarePassword(ctx context.Context, hostUUIDs []string, uuid, password string) error {
+	raw, err := marshalCommand(uuid, verifyFirmwarePasswordPayload{
+		RequestType: "VerifyFirmwarePassword",
+		Password:    password,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal verify firmware password command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// RotateFileVaultKey sends the homonym [command][1] to the Macs to rotate
+// their FileVault personal recovery key. currentKey unlocks FileVault and the
+// host encrypts the new key with replyCertDER, the DER encoded certificate
+// used to escrow the keys.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/rotatefilevaultkeycommand/command
+func (svc *MDMAppleCommander) RotateFileVaultKey(ctx context.Context, hostUUIDs []string, uuid, currentKey string, replyCertDER []byte) error {
+	raw, err := marshalCommand(uuid, rotateFileVaultKeyPayload{
+		RequestType:                "RotateFileVaultKey",
+		KeyType:                    "personal",
+		FileVaultUnlock:            fileVaultUnlock{Password: currentKey},
+		ReplyEncryptionCertificate: replyCertDER,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal rotate FileVault key command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// Settings sends the homonym [command][1] to the devices to change the given
+// settings at once. Most settings are only supported by supervised devices,
+// the result of each setting is reported in the response of the command.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/settingscommand/command
+func (svc *MDMAppleCommander) Settings(ctx context.Context, hostUUIDs []string, uuid string, settings ...Setting) error {
+	if len(settings) == 0 {
+		return ctxerr.New(ctx, "at least one setting is required")
+	}
+
+	items := make([]any, 0, len(settings))
+	for _, setting := range settings {
+		if aa, ok := setting.(ApplicationAttributesSetting); ok && aa.Identifier == "" {
+			return ctxerr.New(ctx, "the identifier of the app is required to set its attributes")
+		}
+		items = append(items, setting.settingItem())
+	}
+
+	raw, err := marshalCommand(uuid, settingsPayload{
+		RequestType: "Settings",
+		Settings:    items,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal settings command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// AccountConfigurationOptions are the options of the AccountConfiguration
+// command.
+type AccountConfigurationOptions struct {
+	// PrimaryAccountFullName and PrimaryAccountUserName pre-fill the primary
+	// account created during the Setup Assistant.
+	PrimaryAccountFullName string
+	PrimaryAccountUserName string
+	// LockPrimaryAccountInfo prevents the user from changing the primary
+	// account full name and user name.
+	LockPrimaryAccountInfo bool
+	// SetPrimarySetupAccountAsRegularUser makes the primary account a regular
+	// (non-admin) user, it requires AdminAccount.
+	SetPrimarySetupAccountAsRegularUser bool
+	// AdminAccount is the optional managed administrator account created during
+	// the Setup Assistant.
+	AdminAccount *AccountConfigurationAdminAccount
+}
+
+// AccountConfigurationAdminAccount is a managed administrator account created
+// by the AccountConfiguration command.
+type AccountConfigurationAdminAccount struct {
+	ShortName string
+	FullName  string
+	// Password is the clear text password of the account, only its salted
+	// SHA-512 PBKDF2 hash is sent to the device.
+	Password string
+	// Hidden hides the account in the users & groups preferences and the login
+	// window.
+	Hidden bool
+}
+
+// AccountConfiguration sends the homonym [command][1] to the devices, to
+// configure the accounts created during the Setup Assistant.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/accountconfigurationcommand/command
+func (svc *MDMAppleCommander) AccountConfiguration(ctx context.Context, hostUUIDs []string, uuid string, opts AccountConfigurationOptions) error {
+	payload := accountConfigurationPayload{
+		RequestType:                         "AccountConfiguration",
+		PrimaryAccountFullName:              opts.PrimaryAccountFullName,
+		PrimaryAccountUserName:              opts.PrimaryAccountUserName,
+		LockPrimaryAccountInfo:              opts.LockPrimaryAccountInfo,
+		SetPrimarySetupAccountAsRegularUser: opts.SetPrimarySetupAccountAsRegularUser,
+	}
+
+	if opts.SetPrimarySetupAccountAsRegularUser && opts.AdminAccount == nil {
+		return ctxerr.New(ctx, "an admin account is required to set the primary account as a regular user")
+	}
+	if admin := opts.AdminAccount; admin != nil {
+		if admin.ShortName == "" || admin.Password == "" {
+			return ctxerr.New(ctx, "the short name and password of the admin account are required")
+		}
+		hash, err := HashAccountPassword(admin.Password)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "hash admin account password")
+		}
+		payload.AutoSetupAdminAccounts = []autoSetupAdminAccount{{
+			ShortName:    admin.ShortName,
+			FullName:     admin.FullName,
+			PasswordHash: hash,
+			Hidden:       admin.Hidden,
+		}}
+	}
+
+	raw, err := marshalCommand(uuid, payload)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal account configuration command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// UnlockUserAccount sends the homonym [command][1] to the macOS devices to
+// unlock the local user account userName, that the system locked after too
+// many failed login attempts. It only applies to Macs with FileVault enabled.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/unlock_user_account
+func (svc *MDMAppleCommander) UnlockUserAccount(ctx context.Context, hostUUIDs []string, uuid, userName string) error {
+	if userName == "" {
+		return ctxerr.New(ctx, "the user name of the account to unlock is required")
+	}
+
+	raw, err := marshalCommand(uuid, unlockUserAccountPayload{
+		RequestType: "UnlockUserAccount",
+		UserName:    userName,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal unlock user account command")
+	}
 
 	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
 }
@@ -238,49 +547,238 @@ func (svc *MDMAppleCommander) AccountConfiguration(ctx context.Context, hostUUID
 //
 // [1]: https://developer.apple.com/documentation/devicemanagement/declarativemanagementcommand
 func (svc *MDMAppleCommander) DeclarativeManagement(ctx context.Context, hostUUIDs []string, uuid string) error {
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
- <!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
- <plist version="1.0">
-   <dict>
-     <key>Command</key>
-     <dict>
-       <key>RequestType</key>
-       <string>DeclarativeManagement</string>
-     </dict>
-
-     <key>CommandUUID</key>
-     <string>%s</string>
-   </dict>
- </plist>`, uuid)
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "DeclarativeManagement"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal declarative management command")
+	}
 
 	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
 }
 
 func (svc *MDMAppleCommander) DeviceConfigured(ctx context.Context, hostUUID, cmdUUID string) error {
-	raw := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-    <key>Command</key>
-    <dict>
-        <key>RequestType</key>
-        <string>DeviceConfigured</string>
-    </dict>
-    <key>CommandUUID</key>
-    <string>%s</string>
-</dict>
-</plist>`, cmdUUID)
+	raw, err := marshalCommand(cmdUUID, requestTypePayload{RequestType: "DeviceConfigured"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal device configured command")
+	}
 
 	return svc.EnqueueCommand(ctx, []string{hostUUID}, raw)
 }
 
+// DeviceInformationQueries are the attributes queried with the
+// DeviceInformation command to populate the details of hosts managed purely
+// via MDM, such as iPhones and iPads.
+var DeviceInformationQueries = []string{
+	"DeviceName",
+	"DeviceCapacity",
+	"AvailableDeviceCapacity",
+	"OSVersion",
+	"BuildVersion",
+	"ProductName",
+	"Model",
+	"ModelName",
+	"SerialNumber",
+	"WiFiMAC",
+}
+
+// DeviceInformation sends the homonym [command][1] to the devices to query
+// the given attributes, or the attributes in DeviceInformationQueries if no
+// query is given. The supported attributes are listed in the [queries][2]
+// documentation.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/device_information
+// [2]: https://developer.apple.com/documentation/devicemanagement/deviceinformationcommand/command/queries
+func (svc *MDMAppleCommander) DeviceInformation(ctx context.Context, hostUUIDs []string, uuid string, queries []string) error {
+	if len(queries) == 0 {
+		queries = DeviceInformationQueries
+	}
+	raw, err := marshalCommand(uuid, deviceInformationPayload{
+		RequestType: "DeviceInformation",
+		Queries:     queries,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal device information command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// InstalledApplicationList sends the homonym [command][1] to the devices to
+// list all the applications installed on them.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/installed_application_list
+func (svc *MDMAppleCommander) InstalledApplicationList(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, installedApplicationListPayload{
+		RequestType:     "InstalledApplicationList",
+		ManagedAppsOnly: false,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal installed application list command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// RestartDevice sends the homonym [command][1] to the devices to restart
+// them. On iOS and iPadOS, the devices must be supervised.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/restart_device
+func (svc *MDMAppleCommander) RestartDevice(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "RestartDevice"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal restart device command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// ShutDownDevice sends the homonym [command][1] to the devices to shut them
+// down. On iOS and iPadOS, the devices must be supervised.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/shut_down_device
+func (svc *MDMAppleCommander) ShutDownDevice(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "ShutDownDevice"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal shut down device command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// SecurityInfo sends the homonym [command][1] to the devices to get their
+// security information, e.g. the status of FileVault, the firewall and System
+// Integrity Protection on macOS.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/security_info
+func (svc *MDMAppleCommander) SecurityInfo(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "SecurityInfo"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal security info command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// CertificateList sends the homonym [command][1] to the devices to get the
+// certificates installed on them, including the identities delivered by SCEP
+// and the certificates delivered by profiles.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/certificate_list
+func (svc *MDMAppleCommander) CertificateList(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "CertificateList"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal certificate list command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// UserList sends the homonym [command][1] to the devices to list the users
+// that have an account on them: the local users of a Mac or the users of a
+// Shared iPad. The user channels of the users reported can then be targeted
+// with EnqueueUserCommand.
+//
+// [1]: https://developer.apple.com/documentation/devicemanagement/userlistcommand/command
+func (svc *MDMAppleCommander) UserList(ctx context.Context, hostUUIDs []string, uuid string) error {
+	raw, err := marshalCommand(uuid, requestTypePayload{RequestType: "UserList"})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal user list command")
+	}
+
+	return svc.EnqueueCommand(ctx, hostUUIDs, raw)
+}
+
+// UserChannel identifies the user channel of a host, the commands enqueued for
+// it are delivered to the user instead of the device. It is used for the
+// users of Shared iPads and the users of Macs that have an MDM user channel.
+type UserChannel struct {
+	HostUUID string
+	// UserID is the UserID reported by the device for the user (the
+	// GeneratedUID of a macOS user).
+	UserID string
+	// UserShortName is the short name (the Managed Apple ID) of a Shared iPad
+	// user, Shared iPad users all report the same UserID.
+	UserShortName string
+}
+
+// EnrollmentID returns the ID of the user channel enrollment, as stored by
+// nanomdm: the host UUID followed by the ID of the user, or by the short name
+// of the user for Shared iPads.
+func (u UserChannel) EnrollmentID() string {
+	userID := u.UserID
+	if userID == "" || userID == mdm.SharediPadUserID {
+		userID = u.UserShortName
+	}
+	return u.HostUUID + ":" + userID
+}
+
+func (u UserChannel) validate() error {
+	if u.HostUUID == "" {
+		return errors.New("the host UUID of the user channel is required")
+	}
+	if u.UserShortName == "" && (u.UserID == "" || u.UserID == mdm.SharediPadUserID) {
+		return errors.New("the user ID or, for Shared iPads, the user short name of the user channel is required")
+	}
+	return nil
+}
+
+// EnqueueUserCommand enqueues the command for the user channel of the users
+// instead of the device channel of their hosts, and sends the push
+// notifications to the user channels.
+func (svc *MDMAppleCommander) EnqueueUserCommand(ctx context.Context, users []UserChannel, rawCommand string) error {
+	enrollmentIDs := make([]string, 0, len(users))
+	for _, u := range users {
+		if err := u.validate(); err != nil {
+			return ctxerr.Wrap(ctx, err, "invalid user channel")
+		}
+		enrollmentIDs = append(enrollmentIDs, u.EnrollmentID())
+	}
+	return svc.EnqueueCommand(ctx, enrollmentIDs, rawCommand)
+}
+
+// InstallUserProfile sends the InstallProfile command to the user channel of
+// the users, to install a user-scoped profile.
+func (svc *MDMAppleCommander) InstallUserProfile(ctx context.Context, users []UserChannel, profile mobileconfig.Mobileconfig, uuid string) error {
+	signedProfile, err := mobileconfig.Sign(profile, svc.config)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "signing profile")
+	}
+
+	raw, err := marshalCommand(uuid, installProfilePayload{
+		RequestType: "InstallProfile",
+		Payload:     signedProfile,
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal install profile command")
+	}
+	err = svc.EnqueueUserCommand(ctx, users, raw)
+	return ctxerr.Wrap(ctx, err, "commander install user profile")
+}
+
+// EnqueueCommandOptions are the options to enqueue a command with
+// EnqueueCommandWithOptions.
+type EnqueueCommandOptions struct {
+	// SkipPush skips the push notifications to the devices, the command is
+	// delivered on their next check-in. It is meant for callers that enqueue
+	// several commands for the same devices and notify them once with
+	// SendNotifications.
+	SkipPush bool
+}
+
 // EnqueueCommand takes care of enqueuing the commands and sending push
-// notifications to the devices.
+// notifications to the devices. The IDs are the enrollment IDs of nanomdm,
+// the host UUIDs for the device channels (see EnqueueUserCommand for the user
+// channels).
 //
-// Always sending the push notification when a command is enqueued was decided
-// internally, leaving making pushes optional as an optimization to be tackled
-// later.
+// The push notifications are sent with every command, see
+// EnqueueCommandWithOptions to skip them and NewCoalescingPusher to batch
+// the pushes of concurrent commands.
 func (svc *MDMAppleCommander) EnqueueCommand(ctx context.Context, hostUUIDs []string, rawCommand string) error {
+	return svc.EnqueueCommandWithOptions(ctx, hostUUIDs, rawCommand, EnqueueCommandOptions{})
+}
+
+// EnqueueCommandWithOptions is like EnqueueCommand, with the push
+// notifications controlled by opts.
+func (svc *MDMAppleCommander) EnqueueCommandWithOptions(ctx context.Context, hostUUIDs []string, rawCommand string, opts EnqueueCommandOptions) error {
 	cmd, err := mdm.DecodeCommand([]byte(rawCommand))
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "decoding command")
@@ -290,14 +788,123 @@ func (svc *MDMAppleCommander) EnqueueCommand(ctx context.Context, hostUUIDs []st
 		return ctxerr.Wrap(ctx, err, "enqueuing command")
 	}
 
-	if err := svc.sendNotifications(ctx, hostUUIDs); err != nil {
+	if opts.SkipPush {
+		return nil
+	}
+	if err := svc.SendNotifications(ctx, hostUUIDs); err != nil {
 		return ctxerr.Wrap(ctx, err, "sending notifications")
 	}
 
 	return nil
 }
 
-func (svc *MDMAppleCommander) sendNotifications(ctx context.Context, hostUUIDs []string) error {
+// EnqueueCommandResult is the result of enqueueing a command with
+// EnqueueCommandInChunks.
+type EnqueueCommandResult struct {
+	// EnqueuedUUIDs are the host UUIDs the command was enqueued for.
+	EnqueuedUUIDs []string
+	// SkippedUUIDs are the host UUIDs that have no enabled MDM enrollment, the
+	// command was not enqueued for them.
+	SkippedUUIDs []string
+	// FailedUUIDs are the host UUIDs the command could not be enqueued for
+	// after all attempts, along with the host UUIDs the command was enqueued
+	// for but that could not be notified via APNs (the command is delivered
+	// on their next check-in).
+	FailedUUIDs []string
+}
+
+// EnqueueCommandInChunks enqueues the command for the hosts in chunks,
+// retrying each chunk on failure, and sends push notifications to the hosts
+// the command was enqueued for. Unlike EnqueueCommand, a failure to enqueue
+// the command for some of the hosts does not prevent enqueueing it for the
+// others, the returned result reports the outcome for each host.
+//
+// An error is returned only if the command is invalid or if it could not be
+// enqueued for any host.
+func (svc *MDMAppleCommander) EnqueueCommandInChunks(ctx context.Context, hostUUIDs []string, rawCommand string) (*EnqueueCommandResult, error) {
+	cmd, err := mdm.DecodeCommand([]byte(rawCommand))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decoding command")
+	}
+
+	chunkSize := svc.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultEnqueueChunkSize
+	}
+
+	var res EnqueueCommandResult
+	var stored bool
+	var lastErr error
+	for start := 0; start < len(hostUUIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(hostUUIDs) {
+			end = len(hostUUIDs)
+		}
+		chunk := hostUUIDs[start:end]
+
+		enabled, err := svc.storage.EnabledEnrollmentIDs(ctx, chunk)
+		if err != nil {
+			lastErr = ctxerr.Wrap(ctx, err, "checking enrollments")
+			res.FailedUUIDs = append(res.FailedUUIDs, chunk...)
+			continue
+		}
+		enabledSet := make(map[string]bool, len(enabled))
+		for _, id := range enabled {
+			enabledSet[id] = true
+		}
+		toEnqueue := make([]string, 0, len(enabled))
+		for _, id := range chunk {
+			if enabledSet[id] {
+				toEnqueue = append(toEnqueue, id)
+				continue
+			}
+			res.SkippedUUIDs = append(res.SkippedUUIDs, id)
+		}
+		if len(toEnqueue) == 0 {
+			continue
+		}
+
+		err = retry.Do(func() error {
+			// the command is stored along with the first chunk that is
+			// successfully enqueued, the next chunks only reference it.
+			if !stored {
+				if _, err := svc.storage.EnqueueCommand(ctx, toEnqueue, cmd); err != nil {
+					return err
+				}
+				stored = true
+				return nil
+			}
+			return svc.storage.EnqueueStoredCommand(ctx, toEnqueue, cmd.CommandUUID)
+		}, retry.WithInterval(svc.chunkRetryInterval), retry.WithBackoffMultiplier(2), retry.WithMaxAttempts(enqueueChunkMaxAttempts))
+		if err != nil {
+			lastErr = ctxerr.Wrap(ctx, err, "enqueuing command")
+			res.FailedUUIDs = append(res.FailedUUIDs, toEnqueue...)
+			continue
+		}
+		res.EnqueuedUUIDs = append(res.EnqueuedUUIDs, toEnqueue...)
+	}
+
+	if len(res.EnqueuedUUIDs) == 0 {
+		if lastErr != nil {
+			return &res, lastErr
+		}
+		return &res, nil
+	}
+
+	if err := svc.SendNotifications(ctx, res.EnqueuedUUIDs); err != nil {
+		var apnsErr *APNSDeliveryError
+		if !errors.As(err, &apnsErr) {
+			return &res, ctxerr.Wrap(ctx, err, "sending notifications")
+		}
+		res.FailedUUIDs = append(res.FailedUUIDs, apnsErr.FailedUUIDs...)
+	}
+	return &res, nil
+}
+
+// SendNotifications sends push notifications to the devices so that they check
+// in and process their pending commands. The APNs delivery failures are
+// returned as an *APNSDeliveryError.
+func (svc *MDMAppleCommander) SendNotifications(ctx context.Context, hostUUIDs []string) error {
 	apnsResponses, err := svc.pusher.Push(ctx, hostUUIDs)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "commander push")
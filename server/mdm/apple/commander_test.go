@@ -3,8 +3,10 @@ package apple_mdm
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -13,6 +15,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push"
 	nanomdm_pushsvc "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push/service"
 	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	svcmock "github.com/fleetdm/fleet/v4/server/service/mock"
 	"github.com/google/uuid"
 	"github.com/groob/plist"
@@ -141,12 +144,737 @@ func TestMDMAppleCommander(t *testing.T) {
 		require.Contains(t, string(cmd.Raw), cmdUUID)
 		return nil
 	}
-	err = cmdr.EraseDevice(ctx, host, cmdUUID)
+	err = cmdr.EraseDevice(ctx, host, cmdUUID, fleet.MDMAppleEraseDeviceOptions{})
 	require.NoError(t, err)
 	require.True(t, mdmStorage.EnqueueDeviceWipeCommandFuncInvoked)
 	mdmStorage.EnqueueDeviceWipeCommandFuncInvoked = false
 	require.True(t, mdmStorage.RetrievePushInfoFuncInvoked)
 	mdmStorage.RetrievePushInfoFuncInvoked = false
+
+	var wipeCmd *mdm.Command
+	mdmStorage.EnqueueDeviceWipeCommandFunc = func(ctx context.Context, gotHost *fleet.Host, cmd *mdm.Command) error {
+		wipeCmd = cmd
+		return nil
+	}
+	err = cmdr.EraseDevice(ctx, host, uuid.New().String(), fleet.MDMAppleEraseDeviceOptions{PINLength: 4})
+	require.NoError(t, err)
+	require.Regexp(t, `<key>PIN</key><string>\d{4}</string>`, string(wipeCmd.Raw))
+	require.Contains(t, string(wipeCmd.Raw), "<key>ObliterationBehavior</key><string>Default</string>")
+
+	err = cmdr.EraseDevice(ctx, host, uuid.New().String(), fleet.MDMAppleEraseDeviceOptions{
+		ObliterationBehavior: fleet.MDMAppleObliterationBehaviorDoNotObliterate,
+		SkipPIN:              true,
+	})
+	require.NoError(t, err)
+	require.NotContains(t, string(wipeCmd.Raw), "<key>PIN</key>")
+	require.Contains(t, string(wipeCmd.Raw), "<key>ObliterationBehavior</key><string>DoNotObliterate</string>")
+
+	wipeCmd = nil
+	err = cmdr.EraseDevice(ctx, host, uuid.New().String(), fleet.MDMAppleEraseDeviceOptions{ObliterationBehavior: "Never"})
+	require.ErrorContains(t, err, "obliteration_behavior must be one of")
+	require.Nil(t, wipeCmd)
+	mdmStorage.EnqueueDeviceWipeCommandFuncInvoked = false
+	mdmStorage.RetrievePushInfoFuncInvoked = false
+
+	var accountCmd *mdm.Command
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		accountCmd = cmd
+		return nil, nil
+	}
+	err = cmdr.AccountConfiguration(ctx, hostUUIDs, uuid.New().String(), AccountConfigurationOptions{
+		PrimaryAccountFullName: "Jane Doe",
+		PrimaryAccountUserName: "jane",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "AccountConfiguration", accountCmd.Command.RequestType)
+	require.Contains(t, string(accountCmd.Raw), "<key>LockPrimaryAccountInfo</key><false/>")
+	require.NotContains(t, string(accountCmd.Raw), "AutoSetupAdminAccounts")
+
+	err = cmdr.AccountConfiguration(ctx, hostUUIDs, uuid.New().String(), AccountConfigurationOptions{
+		PrimaryAccountFullName:              "Jane Doe",
+		PrimaryAccountUserName:              "jane",
+		LockPrimaryAccountInfo:              true,
+		SetPrimarySetupAccountAsRegularUser: true,
+		AdminAccount:                        &AccountConfigurationAdminAccount{ShortName: "fleetadmin", Password: "s3cret", Hidden: true},
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(accountCmd.Raw), "<key>LockPrimaryAccountInfo</key><true/>")
+	require.Contains(t, string(accountCmd.Raw), "<key>SetPrimarySetupAccountAsRegularUser</key><true/>")
+	require.Contains(t, string(accountCmd.Raw), "<key>shortName</key><string>fleetadmin</string>")
+	require.NotContains(t, string(accountCmd.Raw), "s3cret")
+
+	accountCmd = nil
+	err = cmdr.AccountConfiguration(ctx, hostUUIDs, uuid.New().String(), AccountConfigurationOptions{SetPrimarySetupAccountAsRegularUser: true})
+	require.ErrorContains(t, err, "an admin account is required")
+	err = cmdr.AccountConfiguration(ctx, hostUUIDs, uuid.New().String(), AccountConfigurationOptions{
+		AdminAccount: &AccountConfigurationAdminAccount{ShortName: "fleetadmin"},
+	})
+	require.ErrorContains(t, err, "short name and password of the admin account are required")
+	require.Nil(t, accountCmd)
+	mdmStorage.EnqueueCommandFuncInvoked = false
+	mdmStorage.RetrievePushInfoFuncInvoked = false
+
+	for requestType, send := range map[string]func(context.Context, []string, string) error{
+		"RestartDevice":   cmdr.RestartDevice,
+		"ShutDownDevice":  cmdr.ShutDownDevice,
+		"SecurityInfo":    cmdr.SecurityInfo,
+		"CertificateList": cmdr.CertificateList,
+		"UserList":        cmdr.UserList,
+	} {
+		cmdUUID = uuid.New().String()
+		mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+			require.Equal(t, hostUUIDs, id)
+			require.Equal(t, requestType, cmd.Command.RequestType)
+			require.Equal(t, cmdUUID, cmd.CommandUUID)
+			return nil, nil
+		}
+		err = send(ctx, hostUUIDs, cmdUUID)
+		require.NoError(t, err)
+		require.True(t, mdmStorage.EnqueueCommandFuncInvoked)
+		mdmStorage.EnqueueCommandFuncInvoked = false
+		require.True(t, mdmStorage.RetrievePushInfoFuncInvoked)
+		mdmStorage.RetrievePushInfoFuncInvoked = false
+	}
+}
+
+func TestMDMAppleCommanderInstallApplication(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"iphone-uuid"}
+
+	var payload installApplicationPayload
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.Equal(t, "InstallApplication", cmd.Command.RequestType)
+		var full struct {
+			Command installApplicationPayload
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		payload = full.Command
+		return nil, nil
+	}
+
+	err := cmdr.InstallApplication(ctx, hostUUIDs, uuid.New().String(), 361309726, InstallApplicationOptions{Managed: true, Removable: true})
+	require.NoError(t, err)
+	require.Equal(t, installApplicationPayload{
+		RequestType:      "InstallApplication",
+		ITunesStoreID:    361309726,
+		ManagementFlags:  1,
+		InstallAsManaged: true,
+		Options:          installApplicationOptions{PurchaseMethod: 1},
+		Attributes:       installApplicationAttributes{Removable: true},
+	}, payload)
+
+	err = cmdr.InstallApplication(ctx, hostUUIDs, uuid.New().String(), 361309726, InstallApplicationOptions{})
+	require.NoError(t, err)
+	require.Equal(t, installApplicationPayload{
+		RequestType:   "InstallApplication",
+		ITunesStoreID: 361309726,
+		Options:       installApplicationOptions{PurchaseMethod: 1, NotManaged: true},
+	}, payload)
+}
+
+func TestMDMAppleCommanderRemoveApplication(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"iphone-uuid"}
+
+	var payload removeApplicationPayload
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.Equal(t, "RemoveApplication", cmd.Command.RequestType)
+		var full struct {
+			Command removeApplicationPayload
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		payload = full.Command
+		return nil, nil
+	}
+
+	err := cmdr.RemoveApplication(ctx, hostUUIDs, uuid.New().String(), "com.example.app")
+	require.NoError(t, err)
+	require.Equal(t, removeApplicationPayload{RequestType: "RemoveApplication", Identifier: "com.example.app"}, payload)
+}
+
+func TestMDMAppleCommanderUserChannel(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	var pushed []string
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		pushed = ids
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{
+		AppleSCEPCert: "../../service/testdata/server.pem",
+		AppleSCEPKey:  "../../service/testdata/server.key",
+	})
+
+	require.Equal(t, "mac-uuid:user-guid", UserChannel{HostUUID: "mac-uuid", UserID: "user-guid"}.EnrollmentID())
+	require.Equal(t, "ipad-uuid:jane@example.com", UserChannel{
+		HostUUID:      "ipad-uuid",
+		UserID:        mdm.SharediPadUserID,
+		UserShortName: "jane@example.com",
+	}.EnrollmentID())
+
+	users := []UserChannel{
+		{HostUUID: "mac-uuid", UserID: "user-guid"},
+		{HostUUID: "ipad-uuid", UserID: mdm.SharediPadUserID, UserShortName: "jane@example.com"},
+	}
+	wantIDs := []string{"mac-uuid:user-guid", "ipad-uuid:jane@example.com"}
+
+	mc := mobileconfigForTest("com.foo.bar", "com-foo-bar")
+	cmdUUID := uuid.New().String()
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, ids []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, wantIDs, ids)
+		require.Equal(t, "InstallProfile", cmd.Command.RequestType)
+		require.Equal(t, cmdUUID, cmd.CommandUUID)
+		var fullCmd micromdm.CommandPayload
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &fullCmd))
+		p7, err := pkcs7.Parse(fullCmd.Command.InstallProfile.Payload)
+		require.NoError(t, err)
+		require.Equal(t, string(mc), string(p7.Content))
+		return nil, nil
+	}
+	err := cmdr.InstallUserProfile(ctx, users, mc, cmdUUID)
+	require.NoError(t, err)
+	require.True(t, mdmStorage.EnqueueCommandFuncInvoked)
+	require.Equal(t, wantIDs, pushed)
+
+	// the user channel must identify the user
+	mdmStorage.EnqueueCommandFuncInvoked = false
+	for _, u := range []UserChannel{
+		{UserID: "user-guid"},
+		{HostUUID: "mac-uuid"},
+		{HostUUID: "ipad-uuid", UserID: mdm.SharediPadUserID},
+	} {
+		raw, err := marshalCommand(uuid.New().String(), requestTypePayload{RequestType: "ProfileList"})
+		require.NoError(t, err)
+		err = cmdr.EnqueueUserCommand(ctx, []UserChannel{u}, raw)
+		require.ErrorContains(t, err, "invalid user channel")
+	}
+	require.False(t, mdmStorage.EnqueueCommandFuncInvoked)
+}
+
+func TestMDMAppleCommanderLostMode(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"iphone-uuid"}
+
+	var requestType string
+	var payload enableLostModePayload
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		var full struct {
+			Command enableLostModePayload
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		requestType = cmd.Command.RequestType
+		payload = full.Command
+		return nil, nil
+	}
+
+	err := cmdr.EnableLostMode(ctx, hostUUIDs, uuid.New().String(), fleet.HostLostModeSettings{Message: "Please return", PhoneNumber: "555-0100"})
+	require.NoError(t, err)
+	require.Equal(t, "EnableLostMode", requestType)
+	require.Equal(t, enableLostModePayload{RequestType: "EnableLostMode", Message: "Please return", PhoneNumber: "555-0100"}, payload)
+
+	err = cmdr.DeviceLocation(ctx, hostUUIDs, uuid.New().String())
+	require.NoError(t, err)
+	require.Equal(t, "DeviceLocation", requestType)
+
+	err = cmdr.DisableLostMode(ctx, hostUUIDs, uuid.New().String())
+	require.NoError(t, err)
+	require.Equal(t, "DisableLostMode", requestType)
+	require.Equal(t, enableLostModePayload{RequestType: "DisableLostMode"}, payload)
+}
+
+func TestMDMAppleCommanderActivationLockBypassCode(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"iphone-uuid"}
+
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		var full struct {
+			Command requestTypePayload
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		require.Equal(t, cmd.Command.RequestType, full.Command.RequestType)
+		requestTypes = append(requestTypes, full.Command.RequestType)
+		return nil, nil
+	}
+
+	err := cmdr.ActivationLockBypassCode(ctx, hostUUIDs, uuid.New().String())
+	require.NoError(t, err)
+	err = cmdr.ClearActivationLockBypassCode(ctx, hostUUIDs, uuid.New().String())
+	require.NoError(t, err)
+	require.Equal(t, []string{"ActivationLockBypassCode", "ClearActivationLockBypassCode"}, requestTypes)
+}
+
+func TestMDMAppleCommanderRecoveryLock(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"mac-uuid"}
+
+	var raw string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		raw = string(cmd.Raw)
+		return nil, nil
+	}
+
+	// the current password is omitted when setting the first password
+	err := cmdr.SetRecoveryLock(ctx, hostUUIDs, uuid.New().String(), "", "new-password")
+	require.NoError(t, err)
+	var set struct {
+		Command setRecoveryLockPayload
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &set))
+	require.Equal(t, setRecoveryLockPayload{RequestType: "SetRecoveryLock", NewPassword: "new-password"}, set.Command)
+	require.NotContains(t, raw, "CurrentPassword")
+
+	err = cmdr.SetRecoveryLock(ctx, hostUUIDs, uuid.New().String(), "new-password", "<rotated&password>")
+	require.NoError(t, err)
+	require.NoError(t, plist.Unmarshal([]byte(raw), &set))
+	require.Equal(t, setRecoveryLockPayload{RequestType: "SetRecoveryLock", CurrentPassword: "new-password", NewPassword: "<rotated&password>"}, set.Command)
+
+	err = cmdr.VerifyRecoveryLock(ctx, hostUUIDs, uuid.New().String(), "<rotated&password>")
+	require.NoError(t, err)
+	var verify struct {
+		Command verifyRecoveryLockPayload
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &verify))
+	require.Equal(t, verifyRecoveryLockPayload{RequestType: "VerifyRecoveryLock", Password: "<rotated&password>"}, verify.Command)
+}
+
+func TestMDMAppleCommanderFirmwarePassword(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid"}
+
+	var raw, escrowed string
+	mdmStorage.EnqueueSetFirmwarePasswordCommandFunc = func(ctx context.Context, h *fleet.Host, cmd *mdm.Command, newPassword string) error {
+		require.Equal(t, host, h)
+		require.Equal(t, "SetFirmwarePassword", cmd.Command.RequestType)
+		raw = string(cmd.Raw)
+		escrowed = newPassword
+		return nil
+	}
+
+	// the current password is omitted when setting the first password
+	cmdUUID := uuid.New().String()
+	err := cmdr.SetFirmwarePassword(ctx, host, cmdUUID, "")
+	require.NoError(t, err)
+	var set struct {
+		CommandUUID string
+		Command     setFirmwarePasswordPayload
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &set))
+	require.Equal(t, cmdUUID, set.CommandUUID)
+	require.Regexp(t, `^[A-HJ-NP-Z2-9]{4}(-[A-HJ-NP-Z2-9]{4}){2}$`, escrowed)
+	require.Equal(t, setFirmwarePasswordPayload{RequestType: "SetFirmwarePassword", NewPassword: escrowed}, set.Command)
+	require.NotContains(t, raw, "CurrentPassword")
+
+	// rotating the password sends the current one
+	previous := escrowed
+	err = cmdr.SetFirmwarePassword(ctx, host, uuid.New().String(), previous)
+	require.NoError(t, err)
+	require.NoError(t, plist.Unmarshal([]byte(raw), &set))
+	require.NotEqual(t, previous, escrowed)
+	require.Equal(t, setFirmwarePasswordPayload{RequestType: "SetFirmwarePassword", CurrentPassword: previous, NewPassword: escrowed}, set.Command)
+
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		raw = string(cmd.Raw)
+		return nil, nil
+	}
+	err = cmdr.VerifyFirmwarePassword(ctx, []string{host.UUID}, uuid.New().String(), escrowed)
+	require.NoError(t, err)
+	var verify struct {
+		Command verifyFirmwarePasswordPayload
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &verify))
+	require.Equal(t, verifyFirmwarePasswordPayload{RequestType: "VerifyFirmwarePassword", Password: escrowed}, verify.Command)
+}
+
+func TestMDMAppleCommanderRotateFileVaultKey(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"mac-uuid"}
+
+	var raw string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.Equal(t, "RotateFileVaultKey", cmd.Command.RequestType)
+		raw = string(cmd.Raw)
+		return nil, nil
+	}
+
+	err := cmdr.RotateFileVaultKey(ctx, hostUUIDs, uuid.New().String(), "AAAA-BBBB-<CCCC>", []byte("cert-der"))
+	require.NoError(t, err)
+	var full struct {
+		Command rotateFileVaultKeyPayload
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &full))
+	require.Equal(t, rotateFileVaultKeyPayload{
+		RequestType:                "RotateFileVaultKey",
+		KeyType:                    "personal",
+		FileVaultUnlock:            fileVaultUnlock{Password: "AAAA-BBBB-<CCCC>"},
+		ReplyEncryptionCertificate: []byte("cert-der"),
+	}, full.Command)
+}
+
+func TestMDMAppleCommanderSettings(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"iphone-uuid"}
+
+	var raw string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.Equal(t, "Settings", cmd.Command.RequestType)
+		raw = string(cmd.Raw)
+		return nil, nil
+	}
+
+	err := cmdr.Settings(ctx, hostUUIDs, uuid.New().String())
+	require.ErrorContains(t, err, "at least one setting is required")
+	err = cmdr.Settings(ctx, hostUUIDs, uuid.New().String(), ApplicationAttributesSetting{})
+	require.ErrorContains(t, err, "identifier of the app is required")
+	require.False(t, mdmStorage.EnqueueCommandFuncInvoked)
+
+	err = cmdr.Settings(ctx, hostUUIDs, uuid.New().String(),
+		BluetoothSetting{Enabled: true},
+		DataRoamingSetting{},
+		VoiceRoamingSetting{Enabled: true},
+		PersonalHotspotSetting{},
+		ApplicationAttributesSetting{
+			Identifier: "com.example.app",
+			Attributes: ApplicationAttributes{
+				AssociatedDomains: []string{"example.com"},
+				Removable:         ptr.Bool(false),
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.True(t, mdmStorage.EnqueueCommandFuncInvoked)
+
+	var full struct {
+		Command struct {
+			RequestType string
+			Settings    []map[string]any
+		}
+	}
+	require.NoError(t, plist.Unmarshal([]byte(raw), &full))
+	require.Equal(t, "Settings", full.Command.RequestType)
+	require.Equal(t, []map[string]any{
+		{"Item": "Bluetooth", "Enabled": true},
+		{"Item": "DataRoaming", "Enabled": false},
+		{"Item": "VoiceRoaming", "Enabled": true},
+		{"Item": "PersonalHotspot", "Enabled": false},
+		{
+			"Item":       "ApplicationAttributes",
+			"Identifier": "com.example.app",
+			"Attributes": map[string]any{
+				"AssociatedDomains": []any{"example.com"},
+				"Removable":         false,
+			},
+		},
+	}, full.Command.Settings)
+}
+
+func TestMDMAppleCommanderSpecialCharacters(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		return map[string]*push.Response{}, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	hostUUIDs := []string{"host-uuid"}
+
+	var raw []byte
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		raw = cmd.Raw
+		return nil, nil
+	}
+
+	// the values must be escaped, so that they can't close the string element
+	// and inject other keys in the command
+	const value = `Smith & Sons <admin> "it's" </string><key>RequestType</key><string>EraseDevice</string>&amp;`
+
+	cases := []struct {
+		requestType string
+		key         string
+		enqueue     func(uuid string) error
+	}{
+		{"RemoveProfile", "Identifier", func(uuid string) error {
+			return cmdr.RemoveProfile(ctx, hostUUIDs, value, uuid)
+		}},
+		{"InstallEnterpriseApplication", "ManifestURL", func(uuid string) error {
+			return cmdr.InstallEnterpriseApplication(ctx, hostUUIDs, uuid, value)
+		}},
+		{"RemoveApplication", "Identifier", func(uuid string) error {
+			return cmdr.RemoveApplication(ctx, hostUUIDs, uuid, value)
+		}},
+		{"EnableLostMode", "Message", func(uuid string) error {
+			return cmdr.EnableLostMode(ctx, hostUUIDs, uuid, fleet.HostLostModeSettings{Message: value})
+		}},
+		{"EnableLostMode", "Footnote", func(uuid string) error {
+			return cmdr.EnableLostMode(ctx, hostUUIDs, uuid, fleet.HostLostModeSettings{PhoneNumber: "555-0100", Footnote: value})
+		}},
+		{"SetRecoveryLock", "NewPassword", func(uuid string) error {
+			return cmdr.SetRecoveryLock(ctx, hostUUIDs, uuid, "", value)
+		}},
+		{"VerifyRecoveryLock", "Password", func(uuid string) error {
+			return cmdr.VerifyRecoveryLock(ctx, hostUUIDs, uuid, value)
+		}},
+		{"VerifyFirmwarePassword", "Password", func(uuid string) error {
+			return cmdr.VerifyFirmwarePassword(ctx, hostUUIDs, uuid, value)
+		}},
+		{"AccountConfiguration", "PrimaryAccountFullName", func(uuid string) error {
+			return cmdr.AccountConfiguration(ctx, hostUUIDs, uuid, AccountConfigurationOptions{
+				PrimaryAccountFullName: value,
+				PrimaryAccountUserName: "jsmith",
+				LockPrimaryAccountInfo: true,
+			})
+		}},
+		{"AccountConfiguration", "PrimaryAccountUserName", func(uuid string) error {
+			return cmdr.AccountConfiguration(ctx, hostUUIDs, uuid, AccountConfigurationOptions{
+				PrimaryAccountFullName: "John Smith",
+				PrimaryAccountUserName: value,
+				LockPrimaryAccountInfo: true,
+			})
+		}},
+		{"UnlockUserAccount", "UserName", func(uuid string) error {
+			return cmdr.UnlockUserAccount(ctx, hostUUIDs, uuid, value)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.requestType+" "+c.key, func(t *testing.T) {
+			cmdUUID := uuid.New().String()
+			require.NoError(t, c.enqueue(cmdUUID))
+
+			cmd, err := mdm.DecodeCommand(raw)
+			require.NoError(t, err)
+			require.Equal(t, cmdUUID, cmd.CommandUUID)
+			require.Equal(t, c.requestType, cmd.Command.RequestType)
+
+			var full struct {
+				Command map[string]any
+			}
+			require.NoError(t, plist.Unmarshal(raw, &full))
+			require.Equal(t, value, full.Command[c.key])
+			require.Equal(t, c.requestType, full.Command["RequestType"])
+			require.NotContains(t, string(raw), value)
+		})
+	}
+
+	// nested values are escaped too
+	require.NoError(t, cmdr.DeviceInformation(ctx, hostUUIDs, uuid.New().String(), []string{value}))
+	var info struct {
+		Command deviceInformationPayload
+	}
+	require.NoError(t, plist.Unmarshal(raw, &info))
+	require.Equal(t, deviceInformationPayload{RequestType: "DeviceInformation", Queries: []string{value}}, info.Command)
+
+	require.NoError(t, cmdr.Settings(ctx, hostUUIDs, uuid.New().String(), ApplicationAttributesSetting{
+		Identifier: value,
+		Attributes: ApplicationAttributes{AssociatedDomains: []string{value}},
+	}))
+	var settings struct {
+		Command struct {
+			RequestType string
+			Settings    []map[string]any
+		}
+	}
+	require.NoError(t, plist.Unmarshal(raw, &settings))
+	require.Equal(t, "Settings", settings.Command.RequestType)
+	require.Equal(t, []map[string]any{{
+		"Item":       "ApplicationAttributes",
+		"Identifier": value,
+		"Attributes": map[string]any{"AssociatedDomains": []any{value}},
+	}}, settings.Command.Settings)
+}
+
+type pusherFunc func(context.Context, []string) (map[string]*push.Response, error)
+
+func (f pusherFunc) Push(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+	return f(ctx, ids)
+}
+
+func TestMDMAppleCommanderEnqueueCommandInChunks(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+
+	var pushed []string
+	pushFailures := map[string]bool{}
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		pushed = append(pushed, ids...)
+		res := make(map[string]*push.Response, len(ids))
+		for _, id := range ids {
+			res[id] = &push.Response{Id: id}
+			if pushFailures[id] {
+				res[id].Err = errors.New("push failed")
+			}
+		}
+		return res, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+	cmdr.chunkSize = 2
+	cmdr.chunkRetryInterval = time.Millisecond
+
+	cmdUUID := uuid.New().String()
+	rawCmd := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Command</key>
+	<dict>
+		<key>RequestType</key>
+		<string>ShutDownDevice</string>
+	</dict>
+	<key>CommandUUID</key>
+	<string>%s</string>
+</dict>
+</plist>`, cmdUUID)
+
+	// host "C" has no enrollment, host "D" always fails to be enqueued and
+	// hosts "E" and "F" are enqueued on the second attempt
+	mdmStorage.EnabledEnrollmentIDsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+		var enabled []string
+		for _, id := range ids {
+			if id != "C" {
+				enabled = append(enabled, id)
+			}
+		}
+		return enabled, nil
+	}
+	var storedChunks [][]string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, ids []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, cmdUUID, cmd.CommandUUID)
+		storedChunks = append(storedChunks, ids)
+		return nil, nil
+	}
+	var queuedChunks [][]string
+	attempts := map[string]int{}
+	mdmStorage.EnqueueStoredCommandFunc = func(ctx context.Context, ids []string, commandUUID string) error {
+		require.Equal(t, cmdUUID, commandUUID)
+		attempts[ids[0]]++
+		switch ids[0] {
+		case "D":
+			return errors.New("deadlock")
+		case "E":
+			if attempts[ids[0]] == 1 {
+				return errors.New("deadlock")
+			}
+		}
+		queuedChunks = append(queuedChunks, ids)
+		return nil
+	}
+
+	pushFailures["F"] = true
+	res, err := cmdr.EnqueueCommandInChunks(ctx, []string{"A", "B", "C", "D", "E", "F"}, rawCmd)
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B", "E", "F"}, res.EnqueuedUUIDs)
+	require.Equal(t, []string{"C"}, res.SkippedUUIDs)
+	// "F" was enqueued but could not be notified
+	require.Equal(t, []string{"D", "F"}, res.FailedUUIDs)
+	require.Equal(t, [][]string{{"A", "B"}}, storedChunks)
+	require.Equal(t, [][]string{{"E", "F"}}, queuedChunks)
+	require.Equal(t, enqueueChunkMaxAttempts, attempts["D"])
+	require.Equal(t, 2, attempts["E"])
+	require.ElementsMatch(t, []string{"A", "B", "E", "F"}, pushed)
+
+	// nothing is enqueued if no host is enrolled
+	storedChunks, pushed = nil, nil
+	mdmStorage.EnabledEnrollmentIDsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+		return nil, nil
+	}
+	res, err = cmdr.EnqueueCommandInChunks(ctx, []string{"A", "B", "C"}, rawCmd)
+	require.NoError(t, err)
+	require.Empty(t, res.EnqueuedUUIDs)
+	require.Equal(t, []string{"A", "B", "C"}, res.SkippedUUIDs)
+	require.Empty(t, storedChunks)
+	require.Empty(t, pushed)
+
+	// an error is returned if the command could not be enqueued for any host
+	mdmStorage.EnabledEnrollmentIDsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+		return ids, nil
+	}
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, ids []string, cmd *mdm.Command) (map[string]error, error) {
+		return nil, errors.New("deadlock")
+	}
+	res, err = cmdr.EnqueueCommandInChunks(ctx, []string{"A", "B", "C"}, rawCmd)
+	require.ErrorContains(t, err, "deadlock")
+	require.Equal(t, []string{"A", "B", "C"}, res.FailedUUIDs)
+	require.Empty(t, pushed)
+}
+
+func TestMDMAppleCommanderEnqueueCommandSkipPush(t *testing.T) {
+	ctx := context.Background()
+	mdmStorage := &mock.MDMAppleStore{}
+
+	var pushed []string
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		pushed = append(pushed, ids...)
+		return nil, nil
+	})
+	cmdr := NewMDMAppleCommander(mdmStorage, pusher, config.MDMConfig{})
+
+	var enqueued []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, ids []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, "ShutDownDevice", cmd.Command.RequestType)
+		enqueued = append(enqueued, ids...)
+		return nil, nil
+	}
+
+	raw, err := marshalCommand(uuid.New().String(), requestTypePayload{RequestType: "ShutDownDevice"})
+	require.NoError(t, err)
+
+	err = cmdr.EnqueueCommandWithOptions(ctx, []string{"A", "B"}, raw, EnqueueCommandOptions{SkipPush: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B"}, enqueued)
+	require.Empty(t, pushed)
+
+	enqueued = nil
+	err = cmdr.EnqueueCommandWithOptions(ctx, []string{"A", "B"}, raw, EnqueueCommandOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B"}, enqueued)
+	require.Equal(t, []string{"A", "B"}, pushed)
 }
 
 func newMockAPNSPushProviderFactory() (*svcmock.APNSPushProviderFactory, *svcmock.APNSPushProvider) {
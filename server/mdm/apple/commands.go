@@ -0,0 +1,292 @@
+package apple_mdm
+
+import (
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/mdm/apple/appmanifest"
+	"github.com/groob/plist"
+)
+
+// This file contains the payloads of the MDM commands enqueued by Fleet. They
+// are marshaled to plist with marshalCommand, which takes care of escaping
+// the values, including user-provided ones.
+//
+// See https://developer.apple.com/documentation/devicemanagement/commands_and_queries
+// for the reference of each command.
+
+// commandPayload is the common structure all MDM commands use
+type commandPayload struct {
+	CommandUUID string
+	Command     any
+}
+
+// marshalCommand returns the plist document of the command identified by
+// uuid.
+func marshalCommand(uuid string, command any) (string, error) {
+	raw, err := plist.Marshal(commandPayload{
+		CommandUUID: uuid,
+		Command:     command,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal command payload plist: %w", err)
+	}
+	return string(raw), nil
+}
+
+type installProfilePayload struct {
+	RequestType string
+	// Payload is the (signed) profile, it is base64-encoded in the plist.
+	Payload []byte
+}
+
+type removeProfilePayload struct {
+	RequestType string
+	Identifier  string
+}
+
+type deviceLockPayload struct {
+	RequestType string
+	PIN         string
+}
+
+type eraseDevicePayload struct {
+	RequestType          string
+	PIN                  string `plist:",omitempty"`
+	ObliterationBehavior string
+}
+
+// installEnterpriseApplicationPayload installs the application described
+// either by the manifest at ManifestURL or by the embedded Manifest.
+type installEnterpriseApplicationPayload struct {
+	Manifest    *appmanifest.Manifest `plist:",omitempty"`
+	ManifestURL string                `plist:",omitempty"`
+	RequestType string
+}
+
+// installApplicationPayload installs an App Store app, identified by its
+// iTunes Store ID.
+type installApplicationPayload struct {
+	RequestType   string
+	ITunesStoreID int `plist:"iTunesStoreID"`
+	// ManagementFlags is 1 to remove the app when the MDM enrollment profile
+	// is removed.
+	ManagementFlags int `plist:",omitempty"`
+	// InstallAsManaged is only used by macOS.
+	InstallAsManaged bool `plist:",omitempty"`
+	Options          installApplicationOptions
+	Attributes       installApplicationAttributes
+}
+
+type installApplicationOptions struct {
+	// PurchaseMethod is 1 for the apps whose license is assigned to the
+	// device via VPP.
+	PurchaseMethod int
+	NotManaged     bool `plist:",omitempty"`
+}
+
+type installApplicationAttributes struct {
+	Removable bool
+}
+
+// removeApplicationPayload removes a managed app, identified by its bundle
+// identifier.
+type removeApplicationPayload struct {
+	RequestType string
+	Identifier  string
+}
+
+// enableLostModePayload puts a supervised iOS or iPadOS device in Lost Mode,
+// at least one of Message and PhoneNumber is required.
+type enableLostModePayload struct {
+	RequestType string
+	Message     string `plist:",omitempty"`
+	PhoneNumber string `plist:",omitempty"`
+	Footnote    string `plist:",omitempty"`
+}
+
+// setRecoveryLockPayload sets, changes or clears (with an empty NewPassword)
+// the Recovery Lock password of an Apple silicon Mac. CurrentPassword is
+// omitted if the Mac has no password yet.
+type setRecoveryLockPayload struct {
+	RequestType     string
+	CurrentPassword string `plist:",omitempty"`
+	NewPassword     string
+}
+
+// verifyRecoveryLockPayload checks that Password is the Recovery Lock
+// password of the Mac.
+type verifyRecoveryLockPayload struct {
+	RequestType string
+	Password    string
+}
+
+// setFirmwarePasswordPayload sets, changes or clears (with an empty
+// NewPassword) the EFI firmware password of an Intel Mac. CurrentPassword is
+// omitted if the Mac has no password yet.
+type setFirmwarePasswordPayload struct {
+	RequestType     string
+	CurrentPassword string `plist:",omitempty"`
+	NewPassword     string
+}
+
+// verifyFirmwarePasswordPayload checks that Password is the EFI firmware
+// password of the Mac.
+type verifyFirmwarePasswordPayload struct {
+	RequestType string
+	Password    string
+}
+
+// rotateFileVaultKeyPayload rotates the FileVault personal recovery key of a
+// Mac. FileVaultUnlock holds the current key and the new key is returned
+// encrypted with ReplyEncryptionCertificate.
+type rotateFileVaultKeyPayload struct {
+	RequestType                string
+	KeyType                    string
+	FileVaultUnlock            fileVaultUnlock
+	ReplyEncryptionCertificate []byte
+}
+
+type fileVaultUnlock struct {
+	Password string
+}
+
+// settingsPayload changes the settings of the device, each item of Settings
+// is one of the *SettingItem types.
+type settingsPayload struct {
+	RequestType string
+	Settings    []any
+}
+
+// enabledSettingItem is the item of the settings that are turned on or off,
+// such as Bluetooth or DataRoaming.
+type enabledSettingItem struct {
+	Item    string
+	Enabled bool
+}
+
+type applicationAttributesSettingItem struct {
+	Item       string
+	Identifier string
+	Attributes ApplicationAttributes
+}
+
+// Setting is a setting that can be changed with the Settings command, see
+// BluetoothSetting, DataRoamingSetting, VoiceRoamingSetting,
+// PersonalHotspotSetting and ApplicationAttributesSetting.
+type Setting interface {
+	settingItem() any
+}
+
+// BluetoothSetting turns Bluetooth on or off, on supervised iOS and iPadOS
+// devices and on Macs.
+type BluetoothSetting struct {
+	Enabled bool
+}
+
+func (s BluetoothSetting) settingItem() any {
+	return enabledSettingItem{Item: "Bluetooth", Enabled: s.Enabled}
+}
+
+// DataRoamingSetting turns data roaming on or off on iOS and iPadOS devices.
+type DataRoamingSetting struct {
+	Enabled bool
+}
+
+func (s DataRoamingSetting) settingItem() any {
+	return enabledSettingItem{Item: "DataRoaming", Enabled: s.Enabled}
+}
+
+// VoiceRoamingSetting turns voice roaming on or off on iOS devices. Turning
+// voice roaming off also turns data roaming off.
+type VoiceRoamingSetting struct {
+	Enabled bool
+}
+
+func (s VoiceRoamingSetting) settingItem() any {
+	return enabledSettingItem{Item: "VoiceRoaming", Enabled: s.Enabled}
+}
+
+// PersonalHotspotSetting turns Personal Hotspot on or off on iOS and iPadOS
+// devices.
+type PersonalHotspotSetting struct {
+	Enabled bool
+}
+
+func (s PersonalHotspotSetting) settingItem() any {
+	return enabledSettingItem{Item: "PersonalHotspot", Enabled: s.Enabled}
+}
+
+// ApplicationAttributesSetting sets the attributes of the managed app
+// identified by its bundle Identifier on iOS and iPadOS devices.
+type ApplicationAttributesSetting struct {
+	Identifier string
+	Attributes ApplicationAttributes
+}
+
+func (s ApplicationAttributesSetting) settingItem() any {
+	return applicationAttributesSettingItem{
+		Item:       "ApplicationAttributes",
+		Identifier: s.Identifier,
+		Attributes: s.Attributes,
+	}
+}
+
+// ApplicationAttributes are the attributes of a managed app, the attributes
+// that are not set are left unchanged.
+type ApplicationAttributes struct {
+	// VPNUUID is the VPNUUID of the per-app VPN the app uses.
+	VPNUUID string `plist:",omitempty"`
+	// AssociatedDomains are the domains to associate with the app.
+	AssociatedDomains []string `plist:",omitempty"`
+	// AssociatedDomainsEnableDirectDownloads indicates if the associated
+	// domains are downloaded directly instead of through Apple's CDN.
+	AssociatedDomainsEnableDirectDownloads bool `plist:",omitempty"`
+	// Removable indicates if the user can remove the app.
+	Removable *bool `plist:",omitempty"`
+}
+
+type accountConfigurationPayload struct {
+	PrimaryAccountFullName              string
+	PrimaryAccountUserName              string
+	LockPrimaryAccountInfo              bool
+	SetPrimarySetupAccountAsRegularUser bool                    `plist:",omitempty"`
+	AutoSetupAdminAccounts              []autoSetupAdminAccount `plist:",omitempty"`
+	RequestType                         string
+}
+
+// autoSetupAdminAccount is an admin account created by the
+// AccountConfiguration command, PasswordHash is the output of
+// HashAccountPassword.
+type autoSetupAdminAccount struct {
+	ShortName    string `plist:"shortName"`
+	FullName     string `plist:"fullName,omitempty"`
+	PasswordHash []byte `plist:"passwordHash"`
+	Hidden       bool   `plist:"hidden,omitempty"`
+}
+
+// unlockUserAccountPayload unlocks the local user account UserName.
+type unlockUserAccountPayload struct {
+	RequestType string
+	UserName    string
+}
+
+// requestTypePayload is the payload of the commands that only have a
+// request type, such as DeclarativeManagement and DeviceConfigured.
+type requestTypePayload struct {
+	RequestType string
+}
+
+// deviceInformationPayload queries the device for the attributes listed in
+// Queries.
+type deviceInformationPayload struct {
+	RequestType string
+	Queries     []string
+}
+
+// installedApplicationListPayload requests the list of applications
+// installed on the device, restricted to the managed ones if ManagedAppsOnly
+// is set.
+type installedApplicationListPayload struct {
+	RequestType     string
+	ManagedAppsOnly bool
+}
@@ -0,0 +1,162 @@
+package apple_mdm
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/mdm/apple/appmanifest"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
+	"github.com/groob/plist"
+	micromdm "github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalCommand(t *testing.T) {
+	cases := []struct {
+		name        string
+		command     any
+		requestType string
+		check       func(t *testing.T, payload micromdm.CommandPayload)
+	}{
+		{
+			name:        "InstallProfile",
+			command:     installProfilePayload{RequestType: "InstallProfile", Payload: []byte("<profile>")},
+			requestType: "InstallProfile",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Equal(t, []byte("<profile>"), payload.Command.InstallProfile.Payload)
+			},
+		},
+		{
+			name:        "RemoveProfile",
+			command:     removeProfilePayload{RequestType: "RemoveProfile", Identifier: "com.example&<profile>"},
+			requestType: "RemoveProfile",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Equal(t, "com.example&<profile>", payload.Command.RemoveProfile.Identifier)
+			},
+		},
+		{
+			name:        "DeviceLock",
+			command:     deviceLockPayload{RequestType: "DeviceLock", PIN: "123456"},
+			requestType: "DeviceLock",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Equal(t, "123456", payload.Command.DeviceLock.PIN)
+			},
+		},
+		{
+			name:        "EraseDevice",
+			command:     eraseDevicePayload{RequestType: "EraseDevice", PIN: "123456", ObliterationBehavior: "Default"},
+			requestType: "EraseDevice",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Equal(t, "123456", payload.Command.EraseDevice.PIN)
+			},
+		},
+		{
+			name:        "InstallEnterpriseApplication with URL",
+			command:     installEnterpriseApplicationPayload{RequestType: "InstallEnterpriseApplication", ManifestURL: "https://example.com/manifest?a=1&b=2"},
+			requestType: "InstallEnterpriseApplication",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.NotNil(t, payload.Command.InstallEnterpriseApplication.ManifestURL)
+				require.Equal(t, "https://example.com/manifest?a=1&b=2", *payload.Command.InstallEnterpriseApplication.ManifestURL)
+				require.Nil(t, payload.Command.InstallEnterpriseApplication.Manifest)
+			},
+		},
+		{
+			name: "InstallEnterpriseApplication with manifest",
+			command: installEnterpriseApplicationPayload{
+				RequestType: "InstallEnterpriseApplication",
+				Manifest:    appmanifest.NewFromSha([]byte("abc"), "https://example.com/app.pkg"),
+			},
+			requestType: "InstallEnterpriseApplication",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Nil(t, payload.Command.InstallEnterpriseApplication.ManifestURL)
+				require.NotNil(t, payload.Command.InstallEnterpriseApplication.Manifest)
+			},
+		},
+		{
+			name: "AccountConfiguration",
+			command: accountConfigurationPayload{
+				RequestType:            "AccountConfiguration",
+				PrimaryAccountFullName: `</string><key>LockPrimaryAccountInfo</key><false/><string>`,
+				PrimaryAccountUserName: "o'brien & co",
+				LockPrimaryAccountInfo: true,
+			},
+			requestType: "AccountConfiguration",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				cmd := payload.Command.AccountConfiguration
+				require.Equal(t, `</string><key>LockPrimaryAccountInfo</key><false/><string>`, cmd.PrimaryAccountFullName)
+				require.Equal(t, "o'brien & co", cmd.PrimaryAccountUserName)
+				require.True(t, cmd.LockPrimaryAccountInfo)
+				require.False(t, cmd.SetPrimarySetupAccountAsRegularUser)
+				require.Empty(t, cmd.AutoSetupAdminAccounts)
+			},
+		},
+		{
+			name: "AccountConfiguration with admin account",
+			command: accountConfigurationPayload{
+				RequestType:                         "AccountConfiguration",
+				SetPrimarySetupAccountAsRegularUser: true,
+				AutoSetupAdminAccounts: []autoSetupAdminAccount{{
+					ShortName:    "fleetadmin",
+					FullName:     "Fleet Admin",
+					PasswordHash: []byte("hash"),
+					Hidden:       true,
+				}},
+			},
+			requestType: "AccountConfiguration",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				cmd := payload.Command.AccountConfiguration
+				require.False(t, cmd.LockPrimaryAccountInfo)
+				require.True(t, cmd.SetPrimarySetupAccountAsRegularUser)
+				require.Len(t, cmd.AutoSetupAdminAccounts, 1)
+				require.Equal(t, "fleetadmin", cmd.AutoSetupAdminAccounts[0].ShortName)
+				require.Equal(t, "Fleet Admin", cmd.AutoSetupAdminAccounts[0].FullName)
+				require.Equal(t, []byte("hash"), cmd.AutoSetupAdminAccounts[0].PasswordHash)
+				require.True(t, cmd.AutoSetupAdminAccounts[0].Hidden)
+			},
+		},
+		{
+			name:        "DeclarativeManagement",
+			command:     requestTypePayload{RequestType: "DeclarativeManagement"},
+			requestType: "DeclarativeManagement",
+		},
+		{
+			name:        "DeviceConfigured",
+			command:     requestTypePayload{RequestType: "DeviceConfigured"},
+			requestType: "DeviceConfigured",
+		},
+		{
+			name:        "DeviceInformation",
+			command:     deviceInformationPayload{RequestType: "DeviceInformation", Queries: []string{"DeviceName", "OSVersion"}},
+			requestType: "DeviceInformation",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.Equal(t, []string{"DeviceName", "OSVersion"}, payload.Command.DeviceInformation.Queries)
+			},
+		},
+		{
+			name:        "InstalledApplicationList",
+			command:     installedApplicationListPayload{RequestType: "InstalledApplicationList"},
+			requestType: "InstalledApplicationList",
+			check: func(t *testing.T, payload micromdm.CommandPayload) {
+				require.NotNil(t, payload.Command.InstalledApplicationList)
+				require.False(t, payload.Command.InstalledApplicationList.ManagedAppsOnly)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			raw, err := marshalCommand("cmd-uuid", c.command)
+			require.NoError(t, err)
+
+			cmd, err := mdm.DecodeCommand([]byte(raw))
+			require.NoError(t, err)
+			require.Equal(t, "cmd-uuid", cmd.CommandUUID)
+			require.Equal(t, c.requestType, cmd.Command.RequestType)
+
+			if c.check != nil {
+				var payload micromdm.CommandPayload
+				require.NoError(t, plist.Unmarshal([]byte(raw), &payload))
+				c.check(t, payload)
+			}
+		})
+	}
+}
@@ -28,6 +28,11 @@ const (
 	// FleetCARootConfigPayloadIdentifier TODO
 	FleetCARootConfigPayloadIdentifier = "com.fleetdm.caroot"
 
+	// FleetIsolationPayloadIdentifier is the value for the PayloadIdentifier
+	// used by Fleet to restrict the network features of macOS hosts that are
+	// isolated from the network.
+	FleetIsolationPayloadIdentifier = "com.fleetdm.fleet.mdm.isolation"
+
 	// FleetEnrollmentPayloadIdentifier is the value for the PayloadIdentifier used
 	// by Fleet to enroll a device with the MDM server.
 	FleetEnrollmentPayloadIdentifier = "com.fleetdm.fleet.mdm.apple.mdm"
@@ -54,6 +59,7 @@ func FleetPayloadIdentifiers() map[string]struct{} {
 		FleetFileVaultPayloadIdentifier:    {},
 		FleetdConfigPayloadIdentifier:      {},
 		FleetCARootConfigPayloadIdentifier: {},
+		FleetIsolationPayloadIdentifier:    {},
 	}
 }
 
@@ -140,11 +146,10 @@ type payloadSummary struct {
 	Name       string
 }
 
-// payloadSummary attempts to parse the PayloadContent list of the Mobileconfig's TopLevel object.
-// It returns the PayloadType for each PayloadContentItem.
+// payloadContent attempts to parse the PayloadContent list of the Mobileconfig's TopLevel object.
 //
 // See also https://developer.apple.com/documentation/devicemanagement/toplevel
-func (mc Mobileconfig) payloadSummary() ([]payloadSummary, error) {
+func (mc Mobileconfig) payloadContent() ([]map[string]interface{}, error) {
 	mcBytes := mc
 	if mc.isSignedProfile() {
 		profileData, err := getSignedProfileData(mc)
@@ -176,10 +181,23 @@ func (mc Mobileconfig) payloadSummary() ([]payloadSummary, error) {
 		return nil, ErrEmptyPayloadContent
 	}
 
+	return tlo.PayloadContent, nil
+}
+
+// payloadSummary attempts to parse the PayloadContent list of the Mobileconfig's TopLevel object.
+// It returns the PayloadType for each PayloadContentItem.
+//
+// See also https://developer.apple.com/documentation/devicemanagement/toplevel
+func (mc Mobileconfig) payloadSummary() ([]payloadSummary, error) {
+	payloadContent, err := mc.payloadContent()
+	if err != nil {
+		return nil, err
+	}
+
 	// extract the payload types of each payload content item from the array of
 	// payload dictionaries
 	var result []payloadSummary
-	for _, payloadDict := range tlo.PayloadContent {
+	for _, payloadDict := range payloadContent {
 		summary := payloadSummary{}
 
 		pt, ok := payloadDict["PayloadType"]
@@ -254,6 +272,74 @@ func (mc *Mobileconfig) ScreenPayloads() error {
 	return nil
 }
 
+// networkPayloadTypes are the PayloadTypes of the Wi-Fi and VPN payloads that
+// can authenticate with a certificate issued by a SCEP payload of the same
+// profile.
+var networkPayloadTypes = map[string]struct{}{
+	"com.apple.wifi.managed":         {},
+	"com.apple.vpn.managed":          {},
+	"com.apple.vpn.managed.applayer": {},
+}
+
+const scepPayloadType = "com.apple.security.scep"
+
+// HasSCEPBackedNetworkPayload returns true if the profile contains a Wi-Fi or
+// VPN payload that authenticates with the identity certificate of a SCEP
+// payload of the same profile, that is, a payload whose PayloadCertificateUUID
+// (at any nesting level, e.g. in the VPN or IKEv2 dictionaries) references the
+// PayloadUUID of a SCEP payload.
+func (mc Mobileconfig) HasSCEPBackedNetworkPayload() (bool, error) {
+	payloadContent, err := mc.payloadContent()
+	if err != nil {
+		if errors.Is(err, ErrEmptyPayloadContent) || errors.Is(err, ErrEncryptedPayloadContent) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	scepUUIDs := make(map[string]struct{})
+	for _, payloadDict := range payloadContent {
+		if pt, _ := payloadDict["PayloadType"].(string); pt == scepPayloadType {
+			if id, _ := payloadDict["PayloadUUID"].(string); id != "" {
+				scepUUIDs[id] = struct{}{}
+			}
+		}
+	}
+	if len(scepUUIDs) == 0 {
+		return false, nil
+	}
+
+	for _, payloadDict := range payloadContent {
+		pt, _ := payloadDict["PayloadType"].(string)
+		if _, ok := networkPayloadTypes[pt]; !ok {
+			continue
+		}
+		for _, certUUID := range certificateUUIDs(payloadDict) {
+			if _, ok := scepUUIDs[certUUID]; ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// certificateUUIDs returns all the PayloadCertificateUUID values found in the
+// dictionary and its nested dictionaries.
+func certificateUUIDs(dict map[string]interface{}) []string {
+	var uuids []string
+	for k, v := range dict {
+		switch v := v.(type) {
+		case string:
+			if k == "PayloadCertificateUUID" {
+				uuids = append(uuids, v)
+			}
+		case map[string]interface{}:
+			uuids = append(uuids, certificateUUIDs(v)...)
+		}
+	}
+	return uuids
+}
+
 type ErrInvalidPayloadType struct {
 	payloadType string
 }
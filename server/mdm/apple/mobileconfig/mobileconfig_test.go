@@ -51,6 +51,89 @@ func TestSign(t *testing.T) {
 	}
 }
 
+func TestHasSCEPBackedNetworkPayload(t *testing.T) {
+	const scepPayload = `
+		<dict>
+			<key>PayloadType</key>
+			<string>com.apple.security.scep</string>
+			<key>PayloadUUID</key>
+			<string>SCEP-UUID</string>
+			<key>PayloadIdentifier</key>
+			<string>com.example.scep</string>
+		</dict>`
+	wifiPayload := func(certUUID string) string {
+		return `
+		<dict>
+			<key>PayloadType</key>
+			<string>com.apple.wifi.managed</string>
+			<key>PayloadUUID</key>
+			<string>WIFI-UUID</string>
+			<key>SSID_STR</key>
+			<string>corp</string>
+			<key>PayloadCertificateUUID</key>
+			<string>` + certUUID + `</string>
+		</dict>`
+	}
+	vpnPayload := func(certUUID string) string {
+		return `
+		<dict>
+			<key>PayloadType</key>
+			<string>com.apple.vpn.managed</string>
+			<key>PayloadUUID</key>
+			<string>VPN-UUID</string>
+			<key>VPNType</key>
+			<string>IKEv2</string>
+			<key>IKEv2</key>
+			<dict>
+				<key>AuthenticationMethod</key>
+				<string>Certificate</string>
+				<key>PayloadCertificateUUID</key>
+				<string>` + certUUID + `</string>
+			</dict>
+		</dict>`
+	}
+	profile := func(payloads ...string) Mobileconfig {
+		return Mobileconfig(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadContent</key>
+	<array>` + strings.Join(payloads, "") + `
+	</array>
+	<key>PayloadIdentifier</key>
+	<string>com.example.network</string>
+	<key>PayloadDisplayName</key>
+	<string>Network</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+</dict>
+</plist>`)
+	}
+
+	tests := []struct {
+		name    string
+		profile Mobileconfig
+		want    bool
+	}{
+		{"wifi with scep", profile(scepPayload, wifiPayload("SCEP-UUID")), true},
+		{"nested vpn with scep", profile(vpnPayload("SCEP-UUID"), scepPayload), true},
+		{"wifi with other certificate", profile(scepPayload, wifiPayload("PKCS12-UUID")), false},
+		{"wifi without scep", profile(wifiPayload("SCEP-UUID")), false},
+		{"scep only", profile(scepPayload), false},
+		{"empty", profile(), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.profile.HasSCEPBackedNetworkPayload()
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := Mobileconfig("not a profile").HasSCEPBackedNetworkPayload()
+	require.Error(t, err)
+}
+
 var (
 	testCert = []byte(`-----BEGIN CERTIFICATE-----
 MIID6DCCAdACFGX99Sw4aF2qKGLucoIWQRAXHrs1MA0GCSqGSIb3DQEBCwUAMDUx
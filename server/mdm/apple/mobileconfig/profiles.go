@@ -109,3 +109,64 @@ var FleetCARootTemplate = template.Must(template.New("").Option("missingkey=erro
   </dict>
 </plist>
 `))
+
+// FleetIsolationProfile is installed on the macOS hosts that are isolated from
+// the network, along with the firewall rules applied by the isolation script.
+// It turns on the application firewall to block all incoming connections and
+// restricts AirDrop and the modification of the Bluetooth settings.
+var FleetIsolationProfile = Mobileconfig(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+  <dict>
+    <key>PayloadContent</key>
+    <array>
+      <dict>
+        <key>EnableFirewall</key>
+        <true/>
+        <key>BlockAllIncoming</key>
+        <true/>
+        <key>EnableStealthMode</key>
+        <true/>
+        <key>PayloadDisplayName</key>
+        <string>Firewall</string>
+        <key>PayloadIdentifier</key>
+        <string>` + FleetIsolationPayloadIdentifier + `.firewall</string>
+        <key>PayloadType</key>
+        <string>com.apple.security.firewall</string>
+        <key>PayloadUUID</key>
+        <string>5D1B8A3E-2F0C-4E7B-9A61-3C8E0F4B7D12</string>
+        <key>PayloadVersion</key>
+        <integer>1</integer>
+      </dict>
+      <dict>
+        <key>allowAirDrop</key>
+        <false/>
+        <key>allowBluetoothModification</key>
+        <false/>
+        <key>PayloadDisplayName</key>
+        <string>Restrictions</string>
+        <key>PayloadIdentifier</key>
+        <string>` + FleetIsolationPayloadIdentifier + `.restrictions</string>
+        <key>PayloadType</key>
+        <string>com.apple.applicationaccess</string>
+        <key>PayloadUUID</key>
+        <string>A3F06C2B-7E94-4D58-B1C0-96E2D4A8F351</string>
+        <key>PayloadVersion</key>
+        <integer>1</integer>
+      </dict>
+    </array>
+    <key>PayloadDisplayName</key>
+    <string>Fleet network isolation</string>
+    <key>PayloadIdentifier</key>
+    <string>` + FleetIsolationPayloadIdentifier + `</string>
+    <key>PayloadType</key>
+    <string>Configuration</string>
+    <key>PayloadUUID</key>
+    <string>E7C4925A-0B3D-4F61-8D2E-1A5B9C07F6E4</string>
+    <key>PayloadVersion</key>
+    <integer>1</integer>
+    <key>PayloadDescription</key>
+    <string>Restrictions applied while the host is isolated from the network.</string>
+  </dict>
+</plist>
+`)
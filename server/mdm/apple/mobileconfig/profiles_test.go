@@ -40,3 +40,20 @@ func TestFleetdProfileTemplate(t *testing.T) {
 
 	}
 }
+
+func TestFleetIsolationProfile(t *testing.T) {
+	parsed, err := FleetIsolationProfile.ParseConfigProfile()
+	require.NoError(t, err)
+	require.Equal(t, FleetIsolationPayloadIdentifier, parsed.PayloadIdentifier)
+
+	var out map[string]any
+	_, err = plist.Unmarshal(FleetIsolationProfile, &out)
+	require.NoError(t, err)
+	contents, ok := out["PayloadContent"].([]any)
+	require.True(t, ok)
+	require.Len(t, contents, 2)
+	firewall, ok := contents[0].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, "com.apple.security.firewall", firewall["PayloadType"])
+	require.Equal(t, true, firewall["BlockAllIncoming"])
+}
@@ -0,0 +1,105 @@
+package apple_mdm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	nanomdm_push "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push"
+)
+
+// CoalescingPusher is a push notifications sender that batches the pushes
+// requested within a short window and sends them with a single call to the
+// wrapped pusher, each device being notified once per batch. It avoids
+// sending thousands of redundant APNs pushes when commands are enqueued for
+// the same devices concurrently, e.g. the profiles of a team.
+//
+// Push returns once the batch it was added to is sent, with the responses of
+// the requested devices, so the callers wait for up to the window.
+type CoalescingPusher struct {
+	pusher nanomdm_push.Pusher
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *pushBatch
+}
+
+type pushBatch struct {
+	ids  map[string]struct{}
+	done chan struct{}
+
+	// responses and err are set before done is closed.
+	responses map[string]*nanomdm_push.Response
+	err       error
+}
+
+// NewCoalescingPusher returns a pusher that batches the pushes over the
+// window before sending them with pusher. A window of 0 disables the
+// batching, the pushes are sent immediately.
+func NewCoalescingPusher(pusher nanomdm_push.Pusher, window time.Duration) *CoalescingPusher {
+	return &CoalescingPusher{
+		pusher: pusher,
+		window: window,
+	}
+}
+
+// Push implements nanomdm_push.Pusher.
+func (p *CoalescingPusher) Push(ctx context.Context, ids []string) (map[string]*nanomdm_push.Response, error) {
+	if p.window <= 0 || len(ids) == 0 {
+		return p.pusher.Push(ctx, ids)
+	}
+
+	p.mu.Lock()
+	batch := p.pending
+	if batch == nil {
+		batch = &pushBatch{
+			ids:  make(map[string]struct{}, len(ids)),
+			done: make(chan struct{}),
+		}
+		p.pending = batch
+		// the batch is shared by all its callers, so it is not sent with the
+		// context (and values) of the one that started it.
+		time.AfterFunc(p.window, func() { p.send(context.Background(), batch) })
+	}
+	for _, id := range ids {
+		batch.ids[id] = struct{}{}
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		// the devices are still notified with the batch
+		return nil, ctx.Err()
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	res := make(map[string]*nanomdm_push.Response, len(ids))
+	for _, id := range ids {
+		if r, ok := batch.responses[id]; ok {
+			res[id] = r
+		}
+	}
+	return res, nil
+}
+
+func (p *CoalescingPusher) send(ctx context.Context, batch *pushBatch) {
+	// once it's not pending anymore, no devices are added to the batch.
+	p.mu.Lock()
+	if p.pending == batch {
+		p.pending = nil
+	}
+	p.mu.Unlock()
+
+	ids := make([]string, 0, len(batch.ids))
+	for id := range batch.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	batch.responses, batch.err = p.pusher.Push(ctx, ids)
+	close(batch.done)
+}
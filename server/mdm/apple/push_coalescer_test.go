@@ -0,0 +1,118 @@
+package apple_mdm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescingPusher(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var calls [][]string
+	var pushErr error
+	pusher := pusherFunc(func(ctx context.Context, ids []string) (map[string]*push.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, ids)
+		if pushErr != nil {
+			return nil, pushErr
+		}
+		res := make(map[string]*push.Response, len(ids))
+		for _, id := range ids {
+			res[id] = &push.Response{Id: "push-" + id}
+			if id == "C" {
+				res[id].Err = errors.New("push failed")
+			}
+		}
+		return res, nil
+	})
+	// the pusher is called from the batches' goroutines
+	getCalls := func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+	resetCalls := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = nil
+	}
+
+	t.Run("concurrent pushes are batched", func(t *testing.T) {
+		resetCalls()
+		p := NewCoalescingPusher(pusher, 50*time.Millisecond)
+
+		requests := [][]string{{"A", "B"}, {"B", "C"}, {"A"}, {"D"}}
+		results := make([]map[string]*push.Response, len(requests))
+		var wg sync.WaitGroup
+		for i, ids := range requests {
+			wg.Add(1)
+			go func(i int, ids []string) {
+				defer wg.Done()
+				res, err := p.Push(ctx, ids)
+				require.NoError(t, err)
+				results[i] = res
+			}(i, ids)
+		}
+		wg.Wait()
+
+		// each device is notified once
+		require.Equal(t, [][]string{{"A", "B", "C", "D"}}, getCalls())
+		// each caller gets the responses of its devices
+		for i, ids := range requests {
+			require.Len(t, results[i], len(ids))
+			for _, id := range ids {
+				require.Equal(t, "push-"+id, results[i][id].Id)
+			}
+		}
+		require.Error(t, results[1]["C"].Err)
+
+		// the pushes after the batch was sent are in a new batch
+		_, err := p.Push(ctx, []string{"A"})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"A", "B", "C", "D"}, {"A"}}, getCalls())
+	})
+
+	t.Run("push error", func(t *testing.T) {
+		resetCalls()
+		pushErr = errors.New("no push certificate")
+		defer func() { pushErr = nil }()
+		p := NewCoalescingPusher(pusher, time.Millisecond)
+
+		_, err := p.Push(ctx, []string{"A"})
+		require.ErrorIs(t, err, pushErr)
+	})
+
+	t.Run("canceled caller", func(t *testing.T) {
+		resetCalls()
+		p := NewCoalescingPusher(pusher, 50*time.Millisecond)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := p.Push(cancelCtx, []string{"A"})
+		require.ErrorIs(t, err, context.Canceled)
+
+		// the device is still notified with the batch
+		_, err = p.Push(ctx, []string{"B"})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"A", "B"}}, getCalls())
+	})
+
+	t.Run("no window", func(t *testing.T) {
+		resetCalls()
+		p := NewCoalescingPusher(pusher, 0)
+
+		_, err := p.Push(ctx, []string{"B", "A"})
+		require.NoError(t, err)
+		_, err = p.Push(ctx, []string{"A"})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"B", "A"}, {"A"}}, getCalls())
+	})
+}
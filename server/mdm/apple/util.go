@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/binary"
 	"encoding/pem"
@@ -16,6 +17,8 @@ import (
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
+	"github.com/groob/plist"
+	"golang.org/x/crypto/pbkdf2"
 )
 
 // Note Apple rejects CSRs if the key size is not 2048.
@@ -75,6 +78,48 @@ func GenerateRandomPin(length int) string {
 	return fmt.Sprintf(f, v)
 }
 
+// recoveryLockPasswordAlphabet excludes the characters that are easily
+// confused with each other, the password is typed by hand in recoveryOS.
+const recoveryLockPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateRecoveryLockPassword generates a random Recovery Lock password made
+// of 4 groups of 5 characters separated by dashes, e.g. "K7Q2M-XH4NB-...".
+func GenerateRecoveryLockPassword() (string, error) {
+	pwd, err := generateGroupedPassword(4, 5)
+	if err != nil {
+		return "", fmt.Errorf("generate recovery lock password: %w", err)
+	}
+	return pwd, nil
+}
+
+// GenerateFirmwarePassword generates a random EFI firmware password made of 3
+// groups of 4 characters separated by dashes, e.g. "K7Q2-XH4N-BW9C". It uses
+// the same alphabet as the Recovery Lock passwords, as it is typed by hand
+// at the firmware prompt.
+func GenerateFirmwarePassword() (string, error) {
+	pwd, err := generateGroupedPassword(3, 4)
+	if err != nil {
+		return "", fmt.Errorf("generate firmware password: %w", err)
+	}
+	return pwd, nil
+}
+
+func generateGroupedPassword(groups, groupLen int) (string, error) {
+	buf := make([]byte, groups*groupLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, b := range buf {
+		if i > 0 && i%groupLen == 0 {
+			sb.WriteByte('-')
+		}
+		// the alphabet has 32 characters, so the modulo is not biased
+		sb.WriteByte(recoveryLockPasswordAlphabet[int(b)%len(recoveryLockPasswordAlphabet)])
+	}
+	return sb.String(), nil
+}
+
 // FmtErrorChain formats Command error message for macOS MDM v1
 func FmtErrorChain(chain []mdm.ErrorChain) string {
 	var sb strings.Builder
@@ -108,3 +153,41 @@ func EnrollURL(token string, appConfig *fleet.AppConfig) (string, error) {
 	enrollURL.RawQuery = q.Encode()
 	return enrollURL.String(), nil
 }
+
+const (
+	// accountPasswordIterations is the number of PBKDF2 iterations used to
+	// hash the passwords of the accounts created via MDM.
+	accountPasswordIterations = 40000
+	accountPasswordSaltSize   = 32
+	accountPasswordKeySize    = 128
+)
+
+// saltedSHA512PBKDF2 is the format of the password hashes expected by the
+// AccountConfiguration command, the same used by macOS to store the
+// passwords of the local accounts.
+type saltedSHA512PBKDF2 struct {
+	Hash struct {
+		Entropy    []byte `plist:"entropy"`
+		Iterations int    `plist:"iterations"`
+		Salt       []byte `plist:"salt"`
+	} `plist:"SALTED-SHA512-PBKDF2"`
+}
+
+// HashAccountPassword returns the salted SHA-512 PBKDF2 hash of password,
+// encoded as a property list to be used as the passwordHash of the accounts
+// created by the AccountConfiguration command.
+func HashAccountPassword(password string) ([]byte, error) {
+	var hash saltedSHA512PBKDF2
+	hash.Hash.Salt = make([]byte, accountPasswordSaltSize)
+	if _, err := rand.Read(hash.Hash.Salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	hash.Hash.Iterations = accountPasswordIterations
+	hash.Hash.Entropy = pbkdf2.Key([]byte(password), hash.Hash.Salt, accountPasswordIterations, accountPasswordKeySize, sha512.New)
+
+	b, err := plist.Marshal(hash)
+	if err != nil {
+		return nil, fmt.Errorf("marshal password hash: %w", err)
+	}
+	return b, nil
+}
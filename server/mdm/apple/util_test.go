@@ -1,10 +1,14 @@
 package apple_mdm
 
 import (
+	"crypto/sha512"
+	"regexp"
 	"testing"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/groob/plist"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/pbkdf2"
 )
 
 func TestMDMAppleEnrollURL(t *testing.T) {
@@ -36,3 +40,43 @@ func TestMDMAppleEnrollURL(t *testing.T) {
 		require.Equal(t, tt.expectedURL, enrollURL)
 	}
 }
+
+func TestGenerateRecoveryLockPassword(t *testing.T) {
+	re := regexp.MustCompile(`^[A-HJ-NP-Z2-9]{5}(-[A-HJ-NP-Z2-9]{5}){3}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		pwd, err := GenerateRecoveryLockPassword()
+		require.NoError(t, err)
+		require.Regexp(t, re, pwd)
+		require.False(t, seen[pwd])
+		seen[pwd] = true
+	}
+}
+
+func TestGenerateFirmwarePassword(t *testing.T) {
+	re := regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}(-[A-HJ-NP-Z2-9]{4}){2}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		pwd, err := GenerateFirmwarePassword()
+		require.NoError(t, err)
+		require.Regexp(t, re, pwd)
+		require.False(t, seen[pwd])
+		seen[pwd] = true
+	}
+}
+
+func TestHashAccountPassword(t *testing.T) {
+	b, err := HashAccountPassword("s3cret")
+	require.NoError(t, err)
+
+	var hash saltedSHA512PBKDF2
+	require.NoError(t, plist.Unmarshal(b, &hash))
+	require.Equal(t, accountPasswordIterations, hash.Hash.Iterations)
+	require.Len(t, hash.Hash.Salt, accountPasswordSaltSize)
+	require.Equal(t, pbkdf2.Key([]byte("s3cret"), hash.Hash.Salt, accountPasswordIterations, accountPasswordKeySize, sha512.New), hash.Hash.Entropy)
+
+	// the salt is random
+	b2, err := HashAccountPassword("s3cret")
+	require.NoError(t, err)
+	require.NotEqual(t, b, b2)
+}
@@ -0,0 +1,127 @@
+// Package backup encrypts and decrypts the bundles of MDM assets exported by
+// Fleet for disaster recovery or to promote a staging server to production.
+//
+// A bundle is the JSON-encoded fleet.MDMAssetsBundle encrypted with
+// AES-256-GCM, using a key derived from a passphrase with scrypt. The
+// encrypted bundle starts with a header identifying the format, followed by
+// the random salt and nonce.
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"golang.org/x/crypto/scrypt"
+)
+
+// MinPassphraseLength is the minimum length of the passphrase used to
+// encrypt a bundle.
+const MinPassphraseLength = 12
+
+const (
+	header  = "FLEET-MDM-ASSETS\x00\x01"
+	saltLen = 16
+
+	// scrypt parameters recommended for interactive use as of 2017, see the
+	// documentation of scrypt.Key.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// ErrInvalidBundle is returned by Open if the data is not an MDM assets
+	// bundle.
+	ErrInvalidBundle = errors.New("not an MDM assets bundle")
+	// ErrDecrypt is returned by Open if the bundle cannot be decrypted, which
+	// is most likely because the passphrase is wrong.
+	ErrDecrypt = errors.New("cannot decrypt the MDM assets bundle, check the passphrase")
+)
+
+// Seal encodes and encrypts the bundle with the passphrase.
+func Seal(bundle *fleet.MDMAssetsBundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+
+	plainText, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("marshal bundle: %w", err)
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	aesGCM, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(salt)+len(nonce)+len(plainText)+aesGCM.Overhead())
+	out = append(out, header...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	// the header is authenticated so that it cannot be tampered with
+	return aesGCM.Seal(out, nonce, plainText, []byte(header)), nil
+}
+
+// Open decrypts and decodes a bundle encrypted by Seal.
+func Open(data []byte, passphrase string) (*fleet.MDMAssetsBundle, error) {
+	if !bytes.HasPrefix(data, []byte(header)) {
+		return nil, ErrInvalidBundle
+	}
+	data = data[len(header):]
+	if len(data) < saltLen {
+		return nil, ErrInvalidBundle
+	}
+	salt, data := data[:saltLen], data[saltLen:]
+
+	aesGCM, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aesGCM.NonceSize() {
+		return nil, ErrInvalidBundle
+	}
+	nonce, data := data[:aesGCM.NonceSize()], data[aesGCM.NonceSize():]
+	plainText, err := aesGCM.Open(nil, nonce, data, []byte(header))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	var bundle fleet.MDMAssetsBundle
+	if err := json.Unmarshal(plainText, &bundle); err != nil {
+		return nil, fmt.Errorf("unmarshal bundle: %w", err)
+	}
+	if bundle.Version > fleet.MDMAssetsBundleVersion {
+		return nil, fmt.Errorf("unsupported MDM assets bundle version %d, upgrade Fleet to import it", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return aesGCM, nil
+}
@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	bundle := &fleet.MDMAssetsBundle{
+		Version:    fleet.MDMAssetsBundleVersion,
+		ExportedAt: time.Date(2024, 6, 7, 9, 0, 0, 0, time.UTC),
+		Assets: []fleet.MDMBundleAsset{
+			{Name: fleet.TenantMDMAssetAPNSCert, Value: []byte("cert")},
+			{Name: fleet.TenantMDMAssetAPNSKey, Tenant: "acme", Value: []byte("key")},
+		},
+		BootstrapPackages: []fleet.MDMBundleBootstrapPackage{
+			{Team: "Workstations", Name: "bootstrap.pkg", Sha256: []byte{1, 2}, Bytes: []byte("pkg")},
+		},
+	}
+
+	_, err := Seal(bundle, "short")
+	require.ErrorContains(t, err, "at least 12 characters")
+
+	const passphrase = "correct horse battery staple"
+	sealed, err := Seal(bundle, passphrase)
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "bootstrap.pkg")
+
+	// the same bundle is encrypted with a different salt and nonce
+	sealed2, err := Seal(bundle, passphrase)
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, sealed2)
+
+	opened, err := Open(sealed, passphrase)
+	require.NoError(t, err)
+	require.Equal(t, bundle, opened)
+
+	_, err = Open(sealed, "wrong passphrase!")
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = Open(tampered, passphrase)
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = Open([]byte("not a bundle"), passphrase)
+	require.ErrorIs(t, err, ErrInvalidBundle)
+	_, err = Open(sealed[:len(header)+4], passphrase)
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	future := *bundle
+	future.Version = fleet.MDMAssetsBundleVersion + 1
+	sealed, err = Seal(&future, passphrase)
+	require.NoError(t, err)
+	_, err = Open(sealed, passphrase)
+	require.ErrorContains(t, err, "unsupported MDM assets bundle version")
+}
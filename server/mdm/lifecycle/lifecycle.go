@@ -58,7 +58,7 @@ func New(ds fleet.Datastore, logger kitlog.Logger) *HostLifecycle {
 // Do executes the provided HostAction based on the platform requested
 func (t *HostLifecycle) Do(ctx context.Context, opts HostOptions) error {
 	switch opts.Platform {
-	case "darwin":
+	case "darwin", "ios", "ipados":
 		err := t.doDarwin(ctx, opts)
 		return ctxerr.Wrapf(ctx, err, "running darwin lifecycle action %s", opts.Action)
 	case "windows":
@@ -124,6 +124,7 @@ func (t *HostLifecycle) resetDarwin(ctx context.Context, opts HostOptions) error
 		UUID:           opts.UUID,
 		HardwareSerial: opts.HardwareSerial,
 		HardwareModel:  opts.HardwareModel,
+		Platform:       opts.Platform,
 	}
 	if err := t.ds.MDMAppleUpsertHost(ctx, host); err != nil {
 		return ctxerr.Wrap(ctx, err, "upserting mdm host")
@@ -160,6 +161,26 @@ func (t *HostLifecycle) turnOnDarwin(ctx context.Context, opts HostOptions) erro
 		tmID = &info.TeamID
 	}
 
+	if info.Platform == "ios" || info.Platform == "ipados" {
+		// iPhones and iPads can't install fleetd nor a bootstrap package, the
+		// only thing left to do is to release DEP-enrolled devices once their
+		// profiles are installed.
+		if !info.DEPAssignedToFleet && !info.InstalledFromDEP {
+			return nil
+		}
+		t.logger.Log("info", "queueing release task for newly enrolled DEP device", "host_uuid", opts.UUID, "platform", info.Platform)
+		err := worker.QueueAppleMDMJob(
+			ctx,
+			t.ds,
+			t.logger,
+			worker.AppleMDMPostDEPReleaseDeviceTask,
+			opts.UUID,
+			tmID,
+			opts.EnrollReference,
+		)
+		return ctxerr.Wrap(ctx, err, "queue DEP release device task")
+	}
+
 	// TODO: improve this to not enqueue the job if a host that is
 	// assigned in ABM is manually enrolling for some reason.
 	if info.DEPAssignedToFleet || info.InstalledFromDEP {
@@ -2,9 +2,12 @@ package mdmlifecycle
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/worker"
 	kitlog "github.com/go-kit/log"
 	"github.com/stretchr/testify/require"
 )
@@ -41,6 +44,10 @@ func TestDoParamValidation(t *testing.T) {
 		{"darwin", HostActionTurnOff, true},
 		{"darwin", HostActionReset, true},
 		{"darwin", HostActionDelete, true},
+		{"ios", HostActionTurnOn, true},
+		{"ios", HostActionReset, true},
+		{"ipados", HostActionTurnOff, true},
+		{"ipados", HostActionDelete, true},
 		{"windows", HostActionTurnOn, true},
 		{"windows", HostActionTurnOff, true},
 		{"windows", HostActionReset, true},
@@ -59,3 +66,42 @@ func TestDoParamValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestTurnOnIOSAndIPadOS(t *testing.T) {
+	ds := new(mock.Store)
+	lc := New(ds, kitlog.NewNopLogger())
+	ctx := context.Background()
+
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, id string) (*fleet.NanoEnrollment, error) {
+		return &fleet.NanoEnrollment{ID: id, DeviceID: id, Type: "Device", Enabled: true, TokenUpdateTally: 1}, nil
+	}
+	var queued []string
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		var args map[string]any
+		require.NoError(t, json.Unmarshal(*job.Args, &args))
+		queued = append(queued, args["task"].(string))
+		return job, nil
+	}
+
+	cases := []struct {
+		name     string
+		info     fleet.HostMDMCheckinInfo
+		wantJobs []string
+	}{
+		{"manual iPhone", fleet.HostMDMCheckinInfo{Platform: "ios"}, nil},
+		{"DEP iPad", fleet.HostMDMCheckinInfo{Platform: "ipados", DEPAssignedToFleet: true}, []string{string(worker.AppleMDMPostDEPReleaseDeviceTask)}},
+		{"manual mac", fleet.HostMDMCheckinInfo{Platform: "darwin"}, []string{string(worker.AppleMDMPostManualEnrollmentTask)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			queued = nil
+			ds.GetHostMDMCheckinInfoFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMCheckinInfo, error) {
+				info := c.info
+				return &info, nil
+			}
+			err := lc.Do(ctx, HostOptions{Action: HostActionTurnOn, Platform: "darwin", UUID: "uuid"})
+			require.NoError(t, err)
+			require.Equal(t, c.wantJobs, queued)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors from RFC 6238 appendix B (SHA-1), truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(c.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, c.want, got, c.unix)
+	}
+
+	_, err := TOTPCode("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+	code, err := TOTPCode(secret, step)
+	require.NoError(t, err)
+
+	got, ok := ValidateTOTP(secret, code, now, 0)
+	require.True(t, ok)
+	require.Equal(t, step, got)
+
+	// accepted within the allowed clock drift
+	_, ok = ValidateTOTP(secret, code, now.Add(totpPeriod), 0)
+	require.True(t, ok)
+	_, ok = ValidateTOTP(secret, code, now.Add(-totpPeriod), 0)
+	require.True(t, ok)
+	_, ok = ValidateTOTP(secret, code, now.Add(3*totpPeriod), 0)
+	require.False(t, ok)
+
+	// a code cannot be reused
+	_, ok = ValidateTOTP(secret, code, now, step)
+	require.False(t, ok)
+
+	// invalid codes
+	_, ok = ValidateTOTP(secret, "12345", now, 0)
+	require.False(t, ok)
+	_, ok = ValidateTOTP(secret, "abcdef", now, 0)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Fleet", "alice@example.com", "ABCDEF")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/Fleet:alice@example.com?"))
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "ABCDEF", u.Query().Get("secret"))
+	require.Equal(t, "Fleet", u.Query().Get("issuer"))
+	require.Equal(t, "6", u.Query().Get("digits"))
+	require.Equal(t, "30", u.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, c := range codes {
+		require.Len(t, c, 11)
+		require.Equal(t, byte('-'), c[5])
+		require.False(t, seen[c])
+		seen[c] = true
+	}
+
+	require.Equal(t, HashRecoveryCode("abcde-fghjk"), HashRecoveryCode(" ABCDEFGHJK "))
+	require.NotEqual(t, HashRecoveryCode("abcde-fghjk"), HashRecoveryCode("abcde-fghjm"))
+}
@@ -0,0 +1,243 @@
+package mfa
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// WebAuthnTimeout is the time the browser gives the user to complete a
+// WebAuthn ceremony.
+const WebAuthnTimeout = 5 * time.Minute
+
+// base64URL is the encoding used by WebAuthn for binary values in JSON.
+var base64URL = base64.RawURLEncoding
+
+// RelyingParty identifies the Fleet server to WebAuthn authenticators.
+type RelyingParty struct {
+	// ID is the RP ID, the host name of the Fleet server.
+	ID string
+	// Origin is the origin (scheme, host and port) of the Fleet server.
+	Origin string
+	// Name is the human-readable name of the relying party.
+	Name string
+}
+
+// NewRelyingParty returns the relying party for the Fleet server reachable
+// at serverURL.
+func NewRelyingParty(serverURL string) (*RelyingParty, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse server url: %w", err)
+	}
+	if u.Scheme == "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid server url %q", serverURL)
+	}
+	return &RelyingParty{
+		ID:     u.Hostname(),
+		Origin: u.Scheme + "://" + u.Host,
+		Name:   "Fleet",
+	}, nil
+}
+
+// NewChallenge returns a new random WebAuthn challenge.
+func NewChallenge() ([]byte, error) {
+	b, err := protocol.CreateChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("generate webauthn challenge: %w", err)
+	}
+	return b, nil
+}
+
+// CredentialDescriptor identifies a credential in WebAuthn options.
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func newCredentialDescriptors(credentialIDs [][]byte) []CredentialDescriptor {
+	descs := make([]CredentialDescriptor, 0, len(credentialIDs))
+	for _, id := range credentialIDs {
+		descs = append(descs, CredentialDescriptor{Type: "public-key", ID: base64URL.EncodeToString(id)})
+	}
+	return descs
+}
+
+// CreationOptions are the options passed to navigator.credentials.create in
+// the browser to register a new credential. Binary values are base64url
+// encoded.
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+// NewCreationOptions returns the options to register a new credential for
+// the user. Existing credentials of the user are excluded so that the same
+// authenticator is not registered twice.
+func (rp *RelyingParty) NewCreationOptions(challenge []byte, userID uint, userName, displayName string, existing [][]byte) *CreationOptions {
+	var opts CreationOptions
+	opts.Challenge = base64URL.EncodeToString(challenge)
+	opts.RP.ID = rp.ID
+	opts.RP.Name = rp.Name
+
+	var uid [8]byte
+	binary.BigEndian.PutUint64(uid[:], uint64(userID))
+	opts.User.ID = base64URL.EncodeToString(uid[:])
+	opts.User.Name = userName
+	opts.User.DisplayName = displayName
+
+	for _, alg := range []webauthncose.COSEAlgorithmIdentifier{webauthncose.AlgES256, webauthncose.AlgEdDSA, webauthncose.AlgRS256} {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{Type: "public-key", Alg: int(alg)})
+	}
+	opts.Timeout = WebAuthnTimeout.Milliseconds()
+	opts.Attestation = "none"
+	opts.ExcludeCredentials = newCredentialDescriptors(existing)
+	opts.AuthenticatorSelection.UserVerification = "preferred"
+	return &opts
+}
+
+// RequestOptions are the options passed to navigator.credentials.get in the
+// browser to authenticate with a registered credential.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// NewRequestOptions returns the options to authenticate with one of the
+// provided credentials.
+func (rp *RelyingParty) NewRequestOptions(challenge []byte, credentialIDs [][]byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        base64URL.EncodeToString(challenge),
+		RPID:             rp.ID,
+		Timeout:          WebAuthnTimeout.Milliseconds(),
+		AllowCredentials: newCredentialDescriptors(credentialIDs),
+		UserVerification: "preferred",
+	}
+}
+
+// Credential is a credential registered by an authenticator.
+type Credential struct {
+	// ID is the credential ID generated by the authenticator.
+	ID []byte
+	// PublicKey is the COSE-encoded public key of the credential.
+	PublicKey []byte
+	// SignCount is the signature counter reported by the authenticator.
+	SignCount uint32
+}
+
+// VerifyRegistration verifies the response of navigator.credentials.create
+// to the challenge and returns the registered credential. The attestation
+// statement, if any, is verified but not checked against trust anchors, as
+// the creation options request no attestation.
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	resp := protocol.AuthenticatorAttestationResponse{
+		AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientDataJSON},
+		AttestationObject:     attestationObject,
+	}
+	parsed, err := resp.Parse()
+	if err != nil {
+		return nil, webAuthnError("parse attestation response", err)
+	}
+	pcc := &protocol.ParsedCredentialCreationData{
+		Response: *parsed,
+		Raw:      protocol.CredentialCreationResponse{AttestationResponse: resp},
+	}
+	if err := pcc.Verify(base64URL.EncodeToString(challenge), false, rp.ID, []string{rp.Origin}); err != nil {
+		return nil, webAuthnError("verify registration", err)
+	}
+
+	authData := parsed.AttestationObject.AuthData
+	if _, err := webauthncose.ParsePublicKey(authData.AttData.CredentialPublicKey); err != nil {
+		return nil, webAuthnError("parse credential public key", err)
+	}
+	return &Credential{
+		ID:        authData.AttData.CredentialID,
+		PublicKey: authData.AttData.CredentialPublicKey,
+		SignCount: authData.Counter,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of navigator.credentials.get to the
+// challenge, signed with the credential. It returns the new signature
+// counter of the credential.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, cred *Credential, clientDataJSON, rawAuthData, signature []byte) (uint32, error) {
+	car := protocol.CredentialAssertionResponse{
+		PublicKeyCredential: protocol.PublicKeyCredential{
+			Credential: protocol.Credential{ID: base64URL.EncodeToString(cred.ID), Type: string(protocol.PublicKeyCredentialType)},
+			RawID:      cred.ID,
+		},
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientDataJSON},
+			AuthenticatorData:     rawAuthData,
+			Signature:             signature,
+		},
+	}
+	parsed, err := car.Parse()
+	if err != nil {
+		return 0, webAuthnError("parse assertion response", err)
+	}
+	if err := parsed.Verify(base64URL.EncodeToString(challenge), rp.ID, []string{rp.Origin}, "", false, cred.PublicKey); err != nil {
+		return 0, webAuthnError("verify assertion", err)
+	}
+
+	// Authenticators that do not implement a counter always report 0,
+	// otherwise the counter must increase or the authenticator may have been
+	// cloned.
+	signCount := parsed.Response.AuthenticatorData.Counter
+	if (signCount != 0 || cred.SignCount != 0) && signCount <= cred.SignCount {
+		return 0, errors.New("signature counter did not increase")
+	}
+	return signCount, nil
+}
+
+// webAuthnError returns an error with the details of a WebAuthn library
+// error, which are otherwise not part of its message.
+func webAuthnError(msg string, err error) error {
+	var perr *protocol.Error
+	if errors.As(err, &perr) && perr.DevInfo != "" {
+		return fmt.Errorf("%s: %s: %s", msg, perr.Details, perr.DevInfo)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// DecodeBase64URL decodes a binary value sent by the browser, base64url
+// encoded with or without padding.
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64URL.DecodeString(strings.TrimRight(s, "="))
+}
+
+// EncodeBase64URL encodes a binary value to send to the browser.
+func EncodeBase64URL(b []byte) string {
+	return base64URL.EncodeToString(b)
+}
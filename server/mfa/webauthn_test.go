@@ -0,0 +1,185 @@
+package mfa
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeCBOR(t *testing.T, v any) []byte {
+	b, err := webauthncbor.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+type testAuthenticator struct {
+	t         *testing.T
+	rpID      string
+	credID    []byte
+	coseKey   []byte
+	sign      func(data []byte) []byte
+	signCount uint32
+}
+
+func newES256Authenticator(t *testing.T, rpID string) *testAuthenticator {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	x, y := make([]byte, 32), make([]byte, 32)
+	priv.X.FillBytes(x)
+	priv.Y.FillBytes(y)
+	return &testAuthenticator{
+		t:       t,
+		rpID:    rpID,
+		credID:  []byte("es256-credential"),
+		coseKey: encodeCBOR(t, map[int]any{1: 2, 3: -7, -1: 1, -2: x, -3: y}),
+		sign: func(data []byte) []byte {
+			sum := sha256.Sum256(data)
+			sig, err := ecdsa.SignASN1(rand.Reader, priv, sum[:])
+			require.NoError(t, err)
+			return sig
+		},
+	}
+}
+
+func newEdDSAAuthenticator(t *testing.T, rpID string) *testAuthenticator {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return &testAuthenticator{
+		t:       t,
+		rpID:    rpID,
+		credID:  []byte("eddsa-credential"),
+		coseKey: encodeCBOR(t, map[int]any{1: 1, 3: -8, -1: 6, -2: []byte(pub)}),
+		sign: func(data []byte) []byte {
+			return ed25519.Sign(priv, data)
+		},
+	}
+}
+
+func (a *testAuthenticator) authData(flags protocol.AuthenticatorFlags, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	b := append([]byte(nil), rpIDHash[:]...)
+	if attested {
+		flags |= protocol.FlagAttestedCredentialData
+	}
+	b = append(b, byte(flags))
+	b = binary.BigEndian.AppendUint32(b, a.signCount)
+	if attested {
+		b = append(b, make([]byte, 16)...) // AAGUID
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.credID)))
+		b = append(b, a.credID...)
+		b = append(b, a.coseKey...)
+	}
+	return b
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	b, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": EncodeBase64URL(challenge),
+		"origin":    origin,
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func (a *testAuthenticator) register(challenge []byte, origin, format string) ([]byte, []byte) {
+	att := encodeCBOR(a.t, map[string]any{
+		"fmt":      format,
+		"attStmt":  map[string]any{},
+		"authData": a.authData(protocol.FlagUserPresent, true),
+	})
+	return clientDataJSON(a.t, "webauthn.create", challenge, origin), att
+}
+
+func (a *testAuthenticator) assert(challenge []byte, origin string) ([]byte, []byte, []byte) {
+	a.signCount++
+	cd := clientDataJSON(a.t, "webauthn.get", challenge, origin)
+	ad := a.authData(protocol.FlagUserPresent, false)
+	cdHash := sha256.Sum256(cd)
+	sig := a.sign(append(append([]byte(nil), ad...), cdHash[:]...))
+	return cd, ad, sig
+}
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("https://fleet.example.com:8443/prefix")
+	require.NoError(t, err)
+	require.Equal(t, "fleet.example.com", rp.ID)
+	require.Equal(t, "https://fleet.example.com:8443", rp.Origin)
+
+	_, err = NewRelyingParty("")
+	require.Error(t, err)
+}
+
+func TestWebAuthnRegistrationAndAssertion(t *testing.T) {
+	rp, err := NewRelyingParty("https://fleet.example.com")
+	require.NoError(t, err)
+
+	for _, auth := range []*testAuthenticator{
+		newES256Authenticator(t, rp.ID),
+		newEdDSAAuthenticator(t, rp.ID),
+	} {
+		t.Run(string(auth.credID), func(t *testing.T) {
+			challenge, err := NewChallenge()
+			require.NoError(t, err)
+
+			opts := rp.NewCreationOptions(challenge, 42, "alice@example.com", "Alice", nil)
+			require.Equal(t, EncodeBase64URL(challenge), opts.Challenge)
+			require.Equal(t, rp.ID, opts.RP.ID)
+			require.Equal(t, "none", opts.Attestation)
+
+			// wrong origin, wrong challenge, invalid attestation statement,
+			// unsupported attestation format
+			cd, att := auth.register(challenge, "https://evil.example.com", "none")
+			_, err = rp.VerifyRegistration(challenge, cd, att)
+			require.ErrorContains(t, err, "origin")
+			cd, att = auth.register([]byte("other"), rp.Origin, "none")
+			_, err = rp.VerifyRegistration(challenge, cd, att)
+			require.ErrorContains(t, err, "challenge")
+			cd, att = auth.register(challenge, rp.Origin, "packed")
+			_, err = rp.VerifyRegistration(challenge, cd, att)
+			require.Error(t, err)
+			cd, att = auth.register(challenge, rp.Origin, "nope")
+			_, err = rp.VerifyRegistration(challenge, cd, att)
+			require.ErrorContains(t, err, "Attestation format nope is unsupported")
+
+			cd, att = auth.register(challenge, rp.Origin, "none")
+			cred, err := rp.VerifyRegistration(challenge, cd, att)
+			require.NoError(t, err)
+			require.Equal(t, auth.credID, cred.ID)
+			require.Equal(t, auth.coseKey, cred.PublicKey)
+
+			// assertion for another relying party fails
+			other := *auth
+			other.rpID = "other.example.com"
+			cdA, adA, sigA := other.assert(challenge, rp.Origin)
+			_, err = rp.VerifyAssertion(challenge, cred, cdA, adA, sigA)
+			require.ErrorContains(t, err, "RP Hash mismatch")
+
+			// valid assertion
+			cdA, adA, sigA = auth.assert(challenge, rp.Origin)
+			count, err := rp.VerifyAssertion(challenge, cred, cdA, adA, sigA)
+			require.NoError(t, err)
+			require.Equal(t, auth.signCount, count)
+
+			// tampered signature
+			sigA[len(sigA)-1] ^= 0xff
+			_, err = rp.VerifyAssertion(challenge, cred, cdA, adA, sigA)
+			require.ErrorContains(t, err, "assertion signature")
+
+			// replayed counter
+			cred.SignCount = count + 10
+			cdA, adA, sigA = auth.assert(challenge, rp.Origin)
+			_, err = rp.VerifyAssertion(challenge, cred, cdA, adA, sigA)
+			require.ErrorContains(t, err, "counter")
+		})
+	}
+}
@@ -58,6 +58,12 @@ type EnqueueDeviceLockCommandFunc func(ctx context.Context, host *fleet.Host, cm
 
 type EnqueueDeviceWipeCommandFunc func(ctx context.Context, host *fleet.Host, cmd *mdm.Command) error
 
+type EnqueueSetFirmwarePasswordCommandFunc func(ctx context.Context, host *fleet.Host, cmd *mdm.Command, newPassword string) error
+
+type EnabledEnrollmentIDsFunc func(ctx context.Context, ids []string) ([]string, error)
+
+type EnqueueStoredCommandFunc func(ctx context.Context, ids []string, commandUUID string) error
+
 type MDMAppleStore struct {
 	StoreAuthenticateFunc        StoreAuthenticateFunc
 	StoreAuthenticateFuncInvoked bool
@@ -125,6 +131,15 @@ type MDMAppleStore struct {
 	EnqueueDeviceWipeCommandFunc        EnqueueDeviceWipeCommandFunc
 	EnqueueDeviceWipeCommandFuncInvoked bool
 
+	EnqueueSetFirmwarePasswordCommandFunc        EnqueueSetFirmwarePasswordCommandFunc
+	EnqueueSetFirmwarePasswordCommandFuncInvoked bool
+
+	EnabledEnrollmentIDsFunc        EnabledEnrollmentIDsFunc
+	EnabledEnrollmentIDsFuncInvoked bool
+
+	EnqueueStoredCommandFunc        EnqueueStoredCommandFunc
+	EnqueueStoredCommandFuncInvoked bool
+
 	mu sync.Mutex
 }
 
@@ -281,3 +296,24 @@ func (fs *MDMAppleStore) EnqueueDeviceWipeCommand(ctx context.Context, host *fle
 	fs.mu.Unlock()
 	return fs.EnqueueDeviceWipeCommandFunc(ctx, host, cmd)
 }
+
+func (fs *MDMAppleStore) EnqueueSetFirmwarePasswordCommand(ctx context.Context, host *fleet.Host, cmd *mdm.Command, newPassword string) error {
+	fs.mu.Lock()
+	fs.EnqueueSetFirmwarePasswordCommandFuncInvoked = true
+	fs.mu.Unlock()
+	return fs.EnqueueSetFirmwarePasswordCommandFunc(ctx, host, cmd, newPassword)
+}
+
+func (fs *MDMAppleStore) EnabledEnrollmentIDs(ctx context.Context, ids []string) ([]string, error) {
+	fs.mu.Lock()
+	fs.EnabledEnrollmentIDsFuncInvoked = true
+	fs.mu.Unlock()
+	return fs.EnabledEnrollmentIDsFunc(ctx, ids)
+}
+
+func (fs *MDMAppleStore) EnqueueStoredCommand(ctx context.Context, ids []string, commandUUID string) error {
+	fs.mu.Lock()
+	fs.EnqueueStoredCommandFuncInvoked = true
+	fs.mu.Unlock()
+	return fs.EnqueueStoredCommandFunc(ctx, ids, commandUUID)
+}
@@ -173,12 +173,20 @@ type GetHostHealthFunc func(ctx context.Context, id uint) (*fleet.HostHealth, er
 
 type ListHostsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error)
 
+type StreamHostsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions, batchSize int, fn func([]*fleet.Host) error) error
+
+type ListHostChangesFunc func(ctx context.Context, filter fleet.TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*fleet.HostChange, error)
+
 type ListHostsLiteByUUIDsFunc func(ctx context.Context, filter fleet.TeamFilter, uuids []string) ([]*fleet.Host, error)
 
 type ListHostsLiteByIDsFunc func(ctx context.Context, ids []uint) ([]*fleet.Host, error)
 
 type MarkHostsSeenFunc func(ctx context.Context, hostIDs []uint, t time.Time) error
 
+type MarkHostsSeenByChannelFunc func(ctx context.Context, channel fleet.HostSeenChannel, hostIDs []uint, t time.Time) error
+
+type MarkHostMDMSeenFunc func(ctx context.Context, hostUUID string, t time.Time) error
+
 type SearchHostsFunc func(ctx context.Context, filter fleet.TeamFilter, query string, omit ...uint) ([]*fleet.Host, error)
 
 type EnrolledHostIDsFunc func(ctx context.Context) ([]uint, error)
@@ -191,6 +199,8 @@ type GenerateHostStatusStatisticsFunc func(ctx context.Context, filter fleet.Tea
 
 type HostIDsByNameFunc func(ctx context.Context, filter fleet.TeamFilter, hostnames []string) ([]uint, error)
 
+type HostIDsByIdentifiersFunc func(ctx context.Context, filter fleet.TeamFilter, identifiers []string) ([]uint, error)
+
 type HostIDsByOSIDFunc func(ctx context.Context, osID uint, offset int, limit int) ([]uint, error)
 
 type HostMemberOfAllLabelsFunc func(ctx context.Context, hostID uint, labelNames []string) (bool, error)
@@ -279,7 +289,7 @@ type SessionByIDFunc func(ctx context.Context, id uint) (*fleet.Session, error)
 
 type ListSessionsForUserFunc func(ctx context.Context, id uint) ([]*fleet.Session, error)
 
-type NewSessionFunc func(ctx context.Context, userID uint, sessionKey string) (*fleet.Session, error)
+type NewSessionFunc func(ctx context.Context, userID uint, sessionKey string, ipAddress string, userAgent string) (*fleet.Session, error)
 
 type DestroySessionFunc func(ctx context.Context, session *fleet.Session) error
 
@@ -287,6 +297,160 @@ type DestroyAllSessionsForUserFunc func(ctx context.Context, id uint) error
 
 type MarkSessionAccessedFunc func(ctx context.Context, session *fleet.Session) error
 
+type NewAPITokenFunc func(ctx context.Context, token *fleet.APIToken, tokenHash string) (*fleet.APIToken, error)
+
+type APITokenFunc func(ctx context.Context, id uint) (*fleet.APIToken, error)
+
+type APITokenByHashFunc func(ctx context.Context, tokenHash string) (*fleet.APIToken, error)
+
+type ListAPITokensFunc func(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error)
+
+type DeleteAPITokenFunc func(ctx context.Context, id uint) error
+
+type MarkAPITokenUsedFunc func(ctx context.Context, id uint, usedAt time.Time) error
+
+type SetUserTOTPSecretFunc func(ctx context.Context, userID uint, secret string) error
+
+type UserTOTPSecretFunc func(ctx context.Context, userID uint) (*fleet.UserTOTPSecret, error)
+
+type ConfirmUserTOTPSecretFunc func(ctx context.Context, userID uint, step int64) error
+
+type MarkUserTOTPStepUsedFunc func(ctx context.Context, userID uint, step int64) (bool, error)
+
+type DeleteUserTOTPSecretFunc func(ctx context.Context, userID uint) error
+
+type NewUserWebAuthnCredentialFunc func(ctx context.Context, cred *fleet.UserWebAuthnCredential) (*fleet.UserWebAuthnCredential, error)
+
+type ListUserWebAuthnCredentialsFunc func(ctx context.Context, userID uint) ([]*fleet.UserWebAuthnCredential, error)
+
+type MarkUserWebAuthnCredentialUsedFunc func(ctx context.Context, id uint, signCount uint32) error
+
+type DeleteUserWebAuthnCredentialFunc func(ctx context.Context, userID uint, id uint) error
+
+type ReplaceUserMFARecoveryCodesFunc func(ctx context.Context, userID uint, codeHashes []string) error
+
+type UseUserMFARecoveryCodeFunc func(ctx context.Context, userID uint, codeHash string) error
+
+type CountUserMFARecoveryCodesFunc func(ctx context.Context, userID uint) (int, error)
+
+type DeleteUserMFAFunc func(ctx context.Context, userID uint) error
+
+type NewMFAChallengeFunc func(ctx context.Context, challenge *fleet.MFAChallenge) error
+
+type MFAChallengeFunc func(ctx context.Context, token string) (*fleet.MFAChallenge, error)
+
+type RecordMFAChallengeAttemptFunc func(ctx context.Context, token string, maxAttempts int) (bool, error)
+
+type DeleteMFAChallengeFunc func(ctx context.Context, token string) error
+
+type CleanupExpiredMFAChallengesFunc func(ctx context.Context) error
+
+type NewSecretVariableFunc func(ctx context.Context, secret fleet.SecretVariablePayload) (*fleet.SecretVariable, error)
+
+type UpsertSecretVariablesFunc func(ctx context.Context, secrets []fleet.SecretVariablePayload) error
+
+type SecretVariableFunc func(ctx context.Context, id uint) (*fleet.SecretVariable, error)
+
+type ListSecretVariablesFunc func(ctx context.Context) ([]*fleet.SecretVariable, error)
+
+type GetSecretVariablesFunc func(ctx context.Context, names []string) ([]*fleet.SecretVariable, error)
+
+type DeleteSecretVariableFunc func(ctx context.Context, id uint) error
+
+type NewConfigVersionFunc func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error)
+
+type ConfigVersionFunc func(ctx context.Context, id uint) (*fleet.ConfigVersion, error)
+
+type ListConfigVersionsFunc func(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.ConfigVersion, *fleet.PaginationMetadata, error)
+
+type ListEmailTemplatesFunc func(ctx context.Context) ([]*fleet.EmailTemplate, error)
+
+type EmailTemplateFunc func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error)
+
+type SaveEmailTemplateFunc func(ctx context.Context, tmpl *fleet.EmailTemplate) (*fleet.EmailTemplate, error)
+
+type DeleteEmailTemplateFunc func(ctx context.Context, name fleet.EmailTemplateName) error
+
+type ListTranslationsFunc func(ctx context.Context) ([]*fleet.Translations, error)
+
+type TranslationsFunc func(ctx context.Context, locale string) (*fleet.Translations, error)
+
+type ApplyTranslationsFunc func(ctx context.Context, t *fleet.Translations) (*fleet.Translations, error)
+
+type DeleteTranslationsFunc func(ctx context.Context, locale string) error
+
+type NewHostGroupFunc func(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error)
+
+type HostGroupFunc func(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error)
+
+type ListHostGroupsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.HostGroup, error)
+
+type SaveHostGroupFunc func(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error)
+
+type DeleteHostGroupFunc func(ctx context.Context, id uint) error
+
+type AddHostsToHostGroupFunc func(ctx context.Context, groupID uint, hostIDs []uint) error
+
+type RemoveHostsFromHostGroupFunc func(ctx context.Context, groupID uint, hostIDs []uint) error
+
+type GetHostCustomFieldsFunc func(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error)
+
+type UpdateHostCustomFieldsFunc func(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error
+
+type DeleteHostCustomFieldValuesNotInFunc func(ctx context.Context, names []string) error
+
+type ListHostsForWarrantyLookupFunc func(ctx context.Context, vendors []fleet.WarrantyVendor, checkedBefore time.Time, limit int) ([]*fleet.Host, error)
+
+type UpsertHostWarrantiesFunc func(ctx context.Context, warranties []*fleet.HostWarranty) error
+
+type ListHostHealthScoreInputsFunc func(ctx context.Context, afterHostID uint, recoveredSince time.Time, limit int) ([]*fleet.HostHealthScoreInputs, error)
+
+type UpsertHostHealthScoresFunc func(ctx context.Context, scores []*fleet.HostHealthScore) error
+
+type GetHostHealthScoresFunc func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error)
+
+type ListHostsPendingApprovalFunc func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostPendingApproval, error)
+
+type ApproveHostEnrollmentFunc func(ctx context.Context, hostID uint) error
+
+type ListDuplicateHostsFunc func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHostSet, error)
+
+type MergeHostsFunc func(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error
+
+type NewTenantFunc func(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error)
+
+type TenantFunc func(ctx context.Context, id uint) (*fleet.Tenant, error)
+
+type ListTenantsFunc func(ctx context.Context) ([]*fleet.Tenant, error)
+
+type SaveTenantFunc func(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error)
+
+type DeleteTenantFunc func(ctx context.Context, id uint) error
+
+type SetTeamsTenantFunc func(ctx context.Context, tenantID *uint, teamIDs []uint) error
+
+type SetUsersTenantFunc func(ctx context.Context, tenantID *uint, userIDs []uint) error
+
+type TenantAppConfigFunc func(ctx context.Context, tenantID uint) (*fleet.AppConfig, error)
+
+type SetTenantMDMAssetsFunc func(ctx context.Context, tenantID uint, assets []fleet.TenantMDMAsset) error
+
+type GetTenantMDMAssetsFunc func(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error)
+
+type RotateTenantEncryptionKeyFunc func(ctx context.Context, tenantID uint) error
+
+type NewDataExportFunc func(ctx context.Context, export *fleet.DataExport) (*fleet.DataExport, error)
+
+type DataExportFunc func(ctx context.Context, id uint) (*fleet.DataExport, error)
+
+type DataExportContentFunc func(ctx context.Context, id uint) ([]byte, error)
+
+type ListPendingDataExportsFunc func(ctx context.Context) ([]*fleet.DataExport, error)
+
+type CompleteDataExportFunc func(ctx context.Context, id uint, data []byte, exportErr string) error
+
+type CleanupDataExportsFunc func(ctx context.Context, createdBefore time.Time) (int64, error)
+
 type NewAppConfigFunc func(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error)
 
 type AppConfigFunc func(ctx context.Context) (*fleet.AppConfig, error)
@@ -423,12 +587,30 @@ type ListHostPastActivitiesFunc func(ctx context.Context, hostID uint, opt fleet
 
 type IsExecutionPendingForHostFunc func(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error)
 
+type NewAdminNotificationFunc func(ctx context.Context, notification *fleet.AdminNotification) error
+
+type ListAdminNotificationsFunc func(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error)
+
+type CountUnreadAdminNotificationsFunc func(ctx context.Context, userID uint) (int, error)
+
+type MarkAdminNotificationsReadFunc func(ctx context.Context, userID uint, notificationIDs []uint) error
+
+type DeleteAdminNotificationFunc func(ctx context.Context, id uint) error
+
 type ShouldSendStatisticsFunc func(ctx context.Context, frequency time.Duration, config config.FleetConfig) (fleet.StatisticsPayload, bool, error)
 
 type RecordStatisticsSentFunc func(ctx context.Context) error
 
 type CleanupStatisticsFunc func(ctx context.Context) error
 
+type IncrementUsageAnalyticsAPIRequestsFunc func(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error
+
+type RecordUsageAnalyticsHostCountFunc func(ctx context.Context, date time.Time) error
+
+type CleanupUsageAnalyticsFunc func(ctx context.Context, olderThan time.Time) error
+
+type GetUsageAnalyticsDashboardFunc func(ctx context.Context, since time.Time, config config.FleetConfig) (*fleet.UsageAnalyticsDashboard, error)
+
 type ApplyPolicySpecsFunc func(ctx context.Context, authorID uint, specs []*fleet.PolicySpec) error
 
 type NewGlobalPolicyFunc func(ctx context.Context, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error)
@@ -447,6 +629,12 @@ type CountPoliciesFunc func(ctx context.Context, teamID *uint, matchQuery string
 
 type UpdateHostPolicyCountsFunc func(ctx context.Context) error
 
+type RecordPolicyComplianceHistoryFunc func(ctx context.Context, date time.Time) error
+
+type CleanupPolicyComplianceHistoryFunc func(ctx context.Context, olderThan time.Time) error
+
+type GetPolicyComplianceTrendsFunc func(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) ([]fleet.PolicyComplianceDay, error)
+
 type PolicyQueriesForHostFunc func(ctx context.Context, host *fleet.Host) (map[string]string, error)
 
 type GetTeamHostsPolicyMembershipsFunc func(ctx context.Context, domain string, teamID uint, policyIDs []uint) ([]fleet.HostPolicyMembershipData, error)
@@ -467,6 +655,8 @@ type ListSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions) (
 
 type CountSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions) (int, error)
 
+type StreamSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions, batchSize int, fn func([]fleet.Software) error) error
+
 type DeleteSoftwareVulnerabilitiesFunc func(ctx context.Context, vulnerabilities []fleet.SoftwareVulnerability) error
 
 type DeleteOutOfDateVulnerabilitiesFunc func(ctx context.Context, source fleet.VulnerabilitySource, duration time.Duration) error
@@ -513,12 +703,20 @@ type GetLatestCronStatsFunc func(ctx context.Context, name string) ([]fleet.Cron
 
 type InsertCronStatsFunc func(ctx context.Context, statsType fleet.CronStatsType, name string, instance string, status fleet.CronStatsStatus) (int, error)
 
-type UpdateCronStatsFunc func(ctx context.Context, id int, status fleet.CronStatsStatus) error
+type UpdateCronStatsFunc func(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error
 
 type UpdateAllCronStatsForInstanceFunc func(ctx context.Context, instance string, fromStatus fleet.CronStatsStatus, toStatus fleet.CronStatsStatus) error
 
 type CleanupCronStatsFunc func(ctx context.Context) error
 
+type ListLatestCronStatsFunc func(ctx context.Context) ([]fleet.CronStats, error)
+
+type IsCronSchedulePausedFunc func(ctx context.Context, name string) (bool, error)
+
+type SetCronSchedulePausedFunc func(ctx context.Context, name string, paused bool) error
+
+type ListPausedCronSchedulesFunc func(ctx context.Context) ([]string, error)
+
 type UpdateQueryAggregatedStatsFunc func(ctx context.Context) error
 
 type LoadHostByNodeKeyFunc func(ctx context.Context, nodeKey string) (*fleet.Host, error)
@@ -541,6 +739,12 @@ type AsyncBatchSaveHostsScheduledQueryStatsFunc func(ctx context.Context, stats
 
 type UpdateHostSoftwareFunc func(ctx context.Context, hostID uint, software []fleet.Software) (*fleet.UpdateHostSoftwareDBResult, error)
 
+type UpdateHostSoftwareFromSourceFunc func(ctx context.Context, hostID uint, source fleet.SoftwareInventorySource, software []fleet.Software) (*fleet.UpdateHostSoftwareDBResult, error)
+
+type UpdateHostSoftwareUpdatedAtFunc func(ctx context.Context, hostID uint) error
+
+type DeleteHostSoftwareByBundleIdentifierFunc func(ctx context.Context, hostID uint, bundleIdentifier string) error
+
 type UpdateHostSoftwareInstalledPathsFunc func(ctx context.Context, hostID uint, reported map[string]struct{}, mutationResults *fleet.UpdateHostSoftwareDBResult) error
 
 type UpdateHostFunc func(ctx context.Context, host *fleet.Host) error
@@ -579,14 +783,34 @@ type SetHostsDiskEncryptionKeyStatusFunc func(ctx context.Context, hostIDs []uin
 
 type GetHostDiskEncryptionKeyFunc func(ctx context.Context, hostID uint) (*fleet.HostDiskEncryptionKey, error)
 
+type SetHostDiskEncryptionKeyRotationPendingFunc func(ctx context.Context, hostID uint, cmdUUID string) (bool, error)
+
+type UpdateHostDiskEncryptionKeyFromRotateResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, encryptedBase64Key string) error
+
+type SaveLUKSDiskEncryptionKeyFunc func(ctx context.Context, hostID uint, passphrase string, keySlot *uint, clientError string) error
+
+type GetHostLUKSDiskEncryptionKeyFunc func(ctx context.Context, hostID uint) (*fleet.HostDiskEncryptionKey, error)
+
+type SetHostLinuxDiskEncryptionEnforcementFunc func(ctx context.Context, hostID uint, status fleet.LinuxDiskEncryptionEnforcementStatus, detail string) error
+
 type SetDiskEncryptionResetStatusFunc func(ctx context.Context, hostID uint, status bool) error
 
 type GetHostCertAssociationsToExpireFunc func(ctx context.Context, expiryDays int, limit int) ([]fleet.SCEPIdentityAssociation, error)
 
+type GetHostSCEPIdentityAssociationFunc func(ctx context.Context, hostUUID string) (*fleet.SCEPIdentityAssociation, error)
+
 type SetCommandForPendingSCEPRenewalFunc func(ctx context.Context, assocs []fleet.SCEPIdentityAssociation, cmdUUID string) error
 
 type CleanSCEPRenewRefsFunc func(ctx context.Context, hostUUID string) error
 
+type GetMDMAppleConfigProfileByHostCommandUUIDFunc func(ctx context.Context, hostUUID string, cmdUUID string) (*fleet.MDMAppleConfigProfile, error)
+
+type RecordHostMDMAppleProfileCertificateFunc func(ctx context.Context, hostUUID string, profileUUID string) error
+
+type GetHostMDMAppleProfileCertificatesToRenewFunc func(ctx context.Context, installedBefore time.Time, limit int) ([]fleet.HostMDMAppleProfileCertificate, error)
+
+type SetCommandForPendingProfileCertificateRenewalFunc func(ctx context.Context, certs []fleet.HostMDMAppleProfileCertificate, cmdUUID string) error
+
 type UpdateHostMDMProfilesVerificationFunc func(ctx context.Context, host *fleet.Host, toVerify []string, toFail []string, toRetry []string) error
 
 type GetHostMDMProfilesExpectedForVerificationFunc func(ctx context.Context, host *fleet.Host) (map[string]*fleet.ExpectedMDMProfile, error)
@@ -595,7 +819,7 @@ type GetHostMDMProfilesRetryCountsFunc func(ctx context.Context, host *fleet.Hos
 
 type GetHostMDMProfileRetryCountByCommandUUIDFunc func(ctx context.Context, host *fleet.Host, cmdUUID string) (fleet.HostMDMProfileRetryCount, error)
 
-type SetOrUpdateHostOrbitInfoFunc func(ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool) error
+type SetOrUpdateHostOrbitInfoFunc func(ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool, timezone sql.NullString) error
 
 type GetHostOrbitInfoFunc func(ctx context.Context, hostID uint) (*fleet.HostOrbitInfo, error)
 
@@ -603,12 +827,30 @@ type ReplaceHostDeviceMappingFunc func(ctx context.Context, id uint, mappings []
 
 type ReplaceHostBatteriesFunc func(ctx context.Context, id uint, mappings []*fleet.HostBattery) error
 
+type ReplaceHostCertificatesFunc func(ctx context.Context, hostID uint, origin fleet.HostCertificateOrigin, certs []*fleet.HostCertificate) error
+
+type ListHostCertificatesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostCertificate, error)
+
+type SetOrUpdateHostCloudInstanceFunc func(ctx context.Context, hostID uint, instance *fleet.HostCloudInstance) error
+
+type ListCertificateInventoryFunc func(ctx context.Context, filter fleet.TeamFilter, opts fleet.CertificateInventoryListOptions) ([]*fleet.CertificateInventoryItem, int, *fleet.PaginationMetadata, error)
+
+type ListExpiringCertificatesFunc func(ctx context.Context, from time.Time, to time.Time, hostsThreshold uint) ([]*fleet.CertificateInventoryItem, error)
+
 type VerifyEnrollSecretFunc func(ctx context.Context, secret string) (*fleet.EnrollSecret, error)
 
 type EnrollHostFunc func(ctx context.Context, isMDMEnabled bool, osqueryHostId string, hardwareUUID string, hardwareSerial string, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error)
 
 type EnrollOrbitFunc func(ctx context.Context, isMDMEnabled bool, hostInfo fleet.OrbitHostInfo, orbitNodeKey string, teamID *uint) (*fleet.Host, error)
 
+type GetHostEnrollmentCertificateBySHA256Func func(ctx context.Context, sha256 string) (*fleet.HostEnrollmentCertificate, error)
+
+type RecordHostEnrollmentCertificateFunc func(ctx context.Context, cert *fleet.HostEnrollmentCertificate) error
+
+type ListHostEnrollmentCertificatesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostEnrollmentCertificate, error)
+
+type RevokeHostEnrollmentCertificatesFunc func(ctx context.Context, hostID uint) (int, error)
+
 type SerialUpdateHostFunc func(ctx context.Context, host *fleet.Host) error
 
 type NewJobFunc func(ctx context.Context, job *fleet.Job) (*fleet.Job, error)
@@ -621,6 +863,24 @@ type InnoDBStatusFunc func(ctx context.Context) (string, error)
 
 type ProcessListFunc func(ctx context.Context) ([]fleet.MySQLProcess, error)
 
+type ListDatabaseMaintenanceRunsFunc func(ctx context.Context) ([]*fleet.DatabaseMaintenanceRun, error)
+
+type RecordDatabaseMaintenanceRunFunc func(ctx context.Context, run *fleet.DatabaseMaintenanceRun) error
+
+type SnapshotTableSizesFunc func(ctx context.Context) error
+
+type ListTableGrowthFunc func(ctx context.Context) ([]*fleet.TableGrowth, error)
+
+type AnalyzeTablesFunc func(ctx context.Context, tables []string) error
+
+type OptimizeTablesFunc func(ctx context.Context, tables []string) error
+
+type CleanupExpiredSessionsFunc func(ctx context.Context, idleTimeout time.Duration, maxLifetime time.Duration) (int64, error)
+
+type CleanupExpiredInvitesFunc func(ctx context.Context, createdBefore time.Time) (int64, error)
+
+type CleanupOrphanedHostRowsFunc func(ctx context.Context) (int64, error)
+
 type ListWindowsUpdatesByHostIDFunc func(ctx context.Context, hostID uint) ([]fleet.WindowsUpdate, error)
 
 type InsertWindowsUpdatesFunc func(ctx context.Context, hostID uint, updates []fleet.WindowsUpdate) error
@@ -645,6 +905,12 @@ type CountVulnerabilitiesFunc func(ctx context.Context, opt fleet.VulnListOption
 
 type UpdateVulnerabilityHostCountsFunc func(ctx context.Context) error
 
+type UpdateHostCVEDetectionsFunc func(ctx context.Context, now time.Time) error
+
+type ListVulnerabilitySLABreachesFunc func(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) ([]*fleet.VulnerabilitySLABreach, error)
+
+type MarkVulnerabilitySLABreachesNotifiedFunc func(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) error
+
 type NewMDMAppleConfigProfileFunc func(ctx context.Context, p fleet.MDMAppleConfigProfile) (*fleet.MDMAppleConfigProfile, error)
 
 type BulkUpsertMDMAppleConfigProfilesFunc func(ctx context.Context, payload []*fleet.MDMAppleConfigProfile) error
@@ -717,10 +983,16 @@ type GetHostDEPAssignmentFunc func(ctx context.Context, hostID uint) (*fleet.Hos
 
 type GetNanoMDMEnrollmentFunc func(ctx context.Context, id string) (*fleet.NanoEnrollment, error)
 
+type ListNanoMDMUserEnrollmentDeviceIDsFunc func(ctx context.Context, deviceIDs []string) ([]string, error)
+
 type IncreasePolicyAutomationIterationFunc func(ctx context.Context, policyID uint) error
 
 type OutdatedAutomationBatchFunc func(ctx context.Context) ([]fleet.PolicyFailure, error)
 
+type ListPolicyFailingHostsFunc func(ctx context.Context, policyID uint) ([]fleet.PolicySetHost, error)
+
+type StreamPolicyMembershipFunc func(ctx context.Context, policyID uint, passes bool, batchSize int, fn func([]fleet.PolicySetHost) error) error
+
 type ListMDMAppleProfilesToInstallFunc func(ctx context.Context) ([]*fleet.MDMAppleProfilePayload, error)
 
 type ListMDMAppleProfilesToRemoveFunc func(ctx context.Context) ([]*fleet.MDMAppleProfilePayload, error)
@@ -735,6 +1007,12 @@ type UpdateOrDeleteHostMDMAppleProfileFunc func(ctx context.Context, profile *fl
 
 type GetMDMAppleCommandRequestTypeFunc func(ctx context.Context, commandUUID string) (string, error)
 
+type ListIOSAndIPadOSToRefetchFunc func(ctx context.Context, interval time.Duration) ([]string, error)
+
+type ListAppleHostsForMDMSoftwareRefetchFunc func(ctx context.Context, interval time.Duration) ([]string, error)
+
+type ListHostsWithNotNowMDMAppleCommandsFunc func(ctx context.Context, requestType string, retryInterval time.Duration, maxAge time.Duration) ([]string, error)
+
 type GetMDMAppleProfilesSummaryFunc func(ctx context.Context, teamID *uint) (*fleet.MDMProfilesSummary, error)
 
 type InsertMDMIdPAccountFunc func(ctx context.Context, account *fleet.MDMIdPAccount) error
@@ -761,6 +1039,54 @@ type RecordHostBootstrapPackageFunc func(ctx context.Context, commandUUID string
 
 type GetHostMDMMacOSSetupFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error)
 
+type RecordHostFleetdInstallFunc func(ctx context.Context, commandUUID string, hostUUID string, retries uint) error
+
+type GetHostMDMFleetdInstallFunc func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error)
+
+type SetOrUpdateHostMDMAppleSecurityInfoFunc func(ctx context.Context, hostID uint, info *fleet.HostMDMAppleSecurityInfo) error
+
+type GetHostMDMAppleSecurityInfoFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error)
+
+type NewHostLostModeFunc func(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings, enableCommandUUID string) error
+
+type GetHostLostModeFunc func(ctx context.Context, hostID uint) (*fleet.HostLostMode, error)
+
+type SetHostLostModePendingDisableFunc func(ctx context.Context, hostID uint, disableCommandUUID string) error
+
+type UpdateHostLostModeFromAppleMDMResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, requestType string, succeeded bool) error
+
+type NewHostLocationFunc func(ctx context.Context, loc *fleet.HostMDMAppleLocation) error
+
+type GetHostLastLocationFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error)
+
+type SetHostMDMAppleActivationLockBypassCodeFunc func(ctx context.Context, hostUUID string, bypassCode string) error
+
+type GetHostMDMAppleActivationLockBypassCodeFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error)
+
+type SetHostMDMAppleActivationLockBypassCodePendingClearFunc func(ctx context.Context, hostID uint, clearCommandUUID string) error
+
+type UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, succeeded bool) error
+
+type ListHostsForAppleRecoveryLockRotationFunc func(ctx context.Context, rotateBefore time.Time, retryBefore time.Time, limit int) ([]*fleet.HostMDMAppleRecoveryLockPassword, error)
+
+type SetHostMDMAppleRecoveryLockPendingFunc func(ctx context.Context, hostID uint, newPassword string, setCmdUUID string) error
+
+type UpdateHostMDMAppleRecoveryLockFromSetResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, errorMessage string) (*fleet.HostMDMAppleRecoveryLockPassword, error)
+
+type SetHostMDMAppleRecoveryLockPendingVerifyFunc func(ctx context.Context, hostID uint, verifyCmdUUID string) error
+
+type UpdateHostMDMAppleRecoveryLockFromVerifyResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, verified bool) error
+
+type GetHostMDMAppleRecoveryLockPasswordFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error)
+
+type UpdateHostMDMAppleFirmwarePasswordFromSetResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, errorMessage string) (*fleet.HostMDMAppleFirmwarePassword, error)
+
+type SetHostMDMAppleFirmwarePasswordPendingVerifyFunc func(ctx context.Context, hostID uint, verifyCmdUUID string) error
+
+type UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, verified bool) error
+
+type GetHostMDMAppleFirmwarePasswordFunc func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error)
+
 type MDMGetEULAMetadataFunc func(ctx context.Context) (*fleet.MDMEULA, error)
 
 type MDMGetEULABytesFunc func(ctx context.Context, token string) (*fleet.MDMEULA, error)
@@ -841,8 +1167,32 @@ type GetHostMDMWindowsProfilesFunc func(ctx context.Context, hostUUID string) ([
 
 type ListMDMConfigProfilesFunc func(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.MDMConfigProfilePayload, *fleet.PaginationMetadata, error)
 
+type PreviewMDMProfileTargetsFunc func(ctx context.Context, teamID *uint, platform string, labels []fleet.ConfigurationProfileLabel) (*fleet.MDMProfileTargetsPreview, error)
+
 type ResendHostMDMProfileFunc func(ctx context.Context, hostUUID string, profileUUID string) error
 
+type NewMDMProfileRedeliveryCampaignFunc func(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) (*fleet.MDMProfileRedeliveryCampaign, error)
+
+type MDMProfileRedeliveryCampaignFunc func(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error)
+
+type ListMDMProfileRedeliveryCampaignsFunc func(ctx context.Context, opts fleet.MDMProfileRedeliveryCampaignListOptions) ([]*fleet.MDMProfileRedeliveryCampaign, error)
+
+type ListRunningMDMProfileRedeliveryCampaignsFunc func(ctx context.Context) ([]*fleet.MDMProfileRedeliveryCampaign, error)
+
+type UpdateMDMProfileRedeliveryCampaignFunc func(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) error
+
+type RedeliverMDMProfileCampaignBatchFunc func(ctx context.Context, campaignID uint, batchSize uint, now time.Time) (uint, error)
+
+type GetMDMProfileRolloutFunc func(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error)
+
+type ListActiveMDMProfileRolloutsFunc func(ctx context.Context) ([]*fleet.MDMProfileRollout, error)
+
+type GetMDMProfileRolloutResultsFunc func(ctx context.Context, profileUUID string) (*fleet.MDMProfileRolloutResults, error)
+
+type UpdateMDMProfileRolloutStatusFunc func(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error
+
+type CleanupMDMProfileRolloutsFunc func(ctx context.Context) error
+
 type GetHostMDMProfileInstallStatusFunc func(ctx context.Context, hostUUID string, profileUUID string) (fleet.MDMDeliveryStatus, error)
 
 type GetMDMCommandPlatformFunc func(ctx context.Context, commandUUID string) (string, error)
@@ -851,6 +1201,8 @@ type ListMDMCommandsFunc func(ctx context.Context, tmFilter fleet.TeamFilter, li
 
 type GetMDMWindowsBitLockerSummaryFunc func(ctx context.Context, teamID *uint) (*fleet.MDMWindowsBitLockerSummary, error)
 
+type GetMDMLinuxDiskEncryptionSummaryFunc func(ctx context.Context, teamID *uint) (*fleet.MDMLinuxDiskEncryptionSummary, error)
+
 type GetMDMWindowsBitLockerStatusFunc func(ctx context.Context, host *fleet.Host) (*fleet.HostMDMDiskEncryption, error)
 
 type GetMDMWindowsProfilesSummaryFunc func(ctx context.Context, teamID *uint) (*fleet.MDMProfilesSummary, error)
@@ -875,6 +1227,52 @@ type NewMDMAppleDeclarationFunc func(ctx context.Context, declaration *fleet.MDM
 
 type SetOrUpdateMDMAppleDeclarationFunc func(ctx context.Context, declaration *fleet.MDMAppleDeclaration) (*fleet.MDMAppleDeclaration, error)
 
+type NewHostLogCollectionFunc func(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error)
+
+type GetHostLogCollectionFunc func(ctx context.Context, execID string) (*fleet.HostLogCollection, error)
+
+type GetHostLogCollectionDataFunc func(ctx context.Context, execID string) ([]byte, error)
+
+type ListHostLogCollectionsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error)
+
+type ListPendingHostLogCollectionsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error)
+
+type SetHostLogCollectionResultFunc func(ctx context.Context, result *fleet.HostLogCollectionResultPayload) (*fleet.HostLogCollection, error)
+
+type NewHostFileRetrievalFunc func(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error)
+
+type GetHostFileRetrievalFunc func(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error)
+
+type GetHostFileRetrievalDataFunc func(ctx context.Context, execID string) ([]byte, error)
+
+type ListHostFileRetrievalsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error)
+
+type ListPendingHostFileRetrievalsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error)
+
+type SetHostFileRetrievalResultFunc func(ctx context.Context, result *fleet.HostFileRetrievalResultPayload) (*fleet.HostFileRetrieval, error)
+
+type NewHostPprofCaptureFunc func(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error)
+
+type GetHostPprofCaptureFunc func(ctx context.Context, execID string) (*fleet.HostPprofCapture, error)
+
+type GetHostPprofCaptureDataFunc func(ctx context.Context, execID string) ([]byte, error)
+
+type ListHostPprofCapturesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error)
+
+type ListPendingHostPprofCapturesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error)
+
+type SetHostPprofCaptureResultFunc func(ctx context.Context, result *fleet.HostPprofCaptureResultPayload) (*fleet.HostPprofCapture, error)
+
+type NewHostRemoteQueryFunc func(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error)
+
+type GetHostRemoteQueryFunc func(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error)
+
+type ListHostRemoteQueriesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error)
+
+type ListPendingHostRemoteQueriesFunc func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error)
+
+type SetHostRemoteQueryResultFunc func(ctx context.Context, result *fleet.HostRemoteQueryResultPayload) (*fleet.HostRemoteQuery, error)
+
 type NewHostScriptExecutionRequestFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload) (*fleet.HostScriptResult, error)
 
 type SetHostScriptExecutionResultFunc func(ctx context.Context, result *fleet.HostScriptResultPayload) (*fleet.HostScriptResult, error)
@@ -899,6 +1297,26 @@ type GetHostScriptDetailsFunc func(ctx context.Context, hostID uint, teamID *uin
 
 type BatchSetScriptsFunc func(ctx context.Context, tmID *uint, scripts []*fleet.Script) error
 
+type SetScriptSharedWithAllTeamsFunc func(ctx context.Context, scriptID uint, shared bool) error
+
+type SetMDMConfigProfileSharedWithAllTeamsFunc func(ctx context.Context, profileUUID string, shared bool) error
+
+type GetTeamSharedLibraryOptOutsFunc func(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error)
+
+type SetTeamSharedLibraryOptOutsFunc func(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) error
+
+type NewScriptScheduleFunc func(ctx context.Context, schedule *fleet.ScriptSchedule) (*fleet.ScriptSchedule, error)
+
+type ScriptScheduleFunc func(ctx context.Context, id uint) (*fleet.ScriptSchedule, error)
+
+type ListScriptSchedulesFunc func(ctx context.Context, teamID *uint) ([]*fleet.ScriptSchedule, error)
+
+type DeleteScriptScheduleFunc func(ctx context.Context, id uint) error
+
+type ListScriptScheduleHostsFunc func(ctx context.Context) ([]*fleet.ScriptScheduleHost, error)
+
+type NewScheduledHostScriptExecutionRequestFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload, scheduleID uint, localDate string) (*fleet.HostScriptResult, error)
+
 type GetHostLockWipeStatusFunc func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error)
 
 type LockHostViaScriptFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error
@@ -909,12 +1327,22 @@ type UnlockHostManuallyFunc func(ctx context.Context, hostID uint, hostFleetPlat
 
 type CleanMacOSMDMLockFunc func(ctx context.Context, hostUUID string) error
 
+type NewHostLockWipeApprovalRequestFunc func(ctx context.Context, hostID uint, action string, userID uint) error
+
+type GetHostLockWipeApprovalRequestFunc func(ctx context.Context, hostID uint, action string) (*fleet.HostLockWipeApprovalRequest, error)
+
+type ClaimHostLockWipeApprovalRequestFunc func(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error)
+
 type CleanupUnusedScriptContentsFunc func(ctx context.Context) error
 
 type CleanupActivitiesAndAssociatedDataFunc func(ctx context.Context, maxCount int, expiryWindowDays int) error
 
 type WipeHostViaScriptFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error
 
+type IsolateHostViaScriptFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error
+
+type UnisolateHostViaScriptFunc func(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error
+
 type WipeHostViaWindowsMDMFunc func(ctx context.Context, host *fleet.Host, cmd *fleet.MDMWindowsCommand) error
 
 type UpdateHostLockWipeStatusFromAppleMDMResultFunc func(ctx context.Context, hostUUID string, cmdUUID string, requestType string, succeeded bool) error
@@ -1151,6 +1579,12 @@ type DataStore struct {
 	ListHostsFunc        ListHostsFunc
 	ListHostsFuncInvoked bool
 
+	StreamHostsFunc        StreamHostsFunc
+	StreamHostsFuncInvoked bool
+
+	ListHostChangesFunc        ListHostChangesFunc
+	ListHostChangesFuncInvoked bool
+
 	ListHostsLiteByUUIDsFunc        ListHostsLiteByUUIDsFunc
 	ListHostsLiteByUUIDsFuncInvoked bool
 
@@ -1160,6 +1594,12 @@ type DataStore struct {
 	MarkHostsSeenFunc        MarkHostsSeenFunc
 	MarkHostsSeenFuncInvoked bool
 
+	MarkHostsSeenByChannelFunc        MarkHostsSeenByChannelFunc
+	MarkHostsSeenByChannelFuncInvoked bool
+
+	MarkHostMDMSeenFunc        MarkHostMDMSeenFunc
+	MarkHostMDMSeenFuncInvoked bool
+
 	SearchHostsFunc        SearchHostsFunc
 	SearchHostsFuncInvoked bool
 
@@ -1178,6 +1618,9 @@ type DataStore struct {
 	HostIDsByNameFunc        HostIDsByNameFunc
 	HostIDsByNameFuncInvoked bool
 
+	HostIDsByIdentifiersFunc        HostIDsByIdentifiersFunc
+	HostIDsByIdentifiersFuncInvoked bool
+
 	HostIDsByOSIDFunc        HostIDsByOSIDFunc
 	HostIDsByOSIDFuncInvoked bool
 
@@ -1322,61 +1765,292 @@ type DataStore struct {
 	MarkSessionAccessedFunc        MarkSessionAccessedFunc
 	MarkSessionAccessedFuncInvoked bool
 
-	NewAppConfigFunc        NewAppConfigFunc
-	NewAppConfigFuncInvoked bool
+	NewAPITokenFunc        NewAPITokenFunc
+	NewAPITokenFuncInvoked bool
 
-	AppConfigFunc        AppConfigFunc
-	AppConfigFuncInvoked bool
+	APITokenFunc        APITokenFunc
+	APITokenFuncInvoked bool
 
-	SaveAppConfigFunc        SaveAppConfigFunc
-	SaveAppConfigFuncInvoked bool
+	APITokenByHashFunc        APITokenByHashFunc
+	APITokenByHashFuncInvoked bool
 
-	GetEnrollSecretsFunc        GetEnrollSecretsFunc
-	GetEnrollSecretsFuncInvoked bool
+	ListAPITokensFunc        ListAPITokensFunc
+	ListAPITokensFuncInvoked bool
 
-	ApplyEnrollSecretsFunc        ApplyEnrollSecretsFunc
-	ApplyEnrollSecretsFuncInvoked bool
+	DeleteAPITokenFunc        DeleteAPITokenFunc
+	DeleteAPITokenFuncInvoked bool
 
-	AggregateEnrollSecretPerTeamFunc        AggregateEnrollSecretPerTeamFunc
-	AggregateEnrollSecretPerTeamFuncInvoked bool
+	MarkAPITokenUsedFunc        MarkAPITokenUsedFunc
+	MarkAPITokenUsedFuncInvoked bool
 
-	NewInviteFunc        NewInviteFunc
-	NewInviteFuncInvoked bool
+	SetUserTOTPSecretFunc        SetUserTOTPSecretFunc
+	SetUserTOTPSecretFuncInvoked bool
 
-	ListInvitesFunc        ListInvitesFunc
-	ListInvitesFuncInvoked bool
+	UserTOTPSecretFunc        UserTOTPSecretFunc
+	UserTOTPSecretFuncInvoked bool
 
-	InviteFunc        InviteFunc
-	InviteFuncInvoked bool
+	ConfirmUserTOTPSecretFunc        ConfirmUserTOTPSecretFunc
+	ConfirmUserTOTPSecretFuncInvoked bool
 
-	InviteByEmailFunc        InviteByEmailFunc
-	InviteByEmailFuncInvoked bool
+	MarkUserTOTPStepUsedFunc        MarkUserTOTPStepUsedFunc
+	MarkUserTOTPStepUsedFuncInvoked bool
 
-	InviteByTokenFunc        InviteByTokenFunc
-	InviteByTokenFuncInvoked bool
+	DeleteUserTOTPSecretFunc        DeleteUserTOTPSecretFunc
+	DeleteUserTOTPSecretFuncInvoked bool
 
-	DeleteInviteFunc        DeleteInviteFunc
-	DeleteInviteFuncInvoked bool
+	NewUserWebAuthnCredentialFunc        NewUserWebAuthnCredentialFunc
+	NewUserWebAuthnCredentialFuncInvoked bool
 
-	UpdateInviteFunc        UpdateInviteFunc
-	UpdateInviteFuncInvoked bool
+	ListUserWebAuthnCredentialsFunc        ListUserWebAuthnCredentialsFunc
+	ListUserWebAuthnCredentialsFuncInvoked bool
 
-	ListScheduledQueriesInPackWithStatsFunc        ListScheduledQueriesInPackWithStatsFunc
-	ListScheduledQueriesInPackWithStatsFuncInvoked bool
+	MarkUserWebAuthnCredentialUsedFunc        MarkUserWebAuthnCredentialUsedFunc
+	MarkUserWebAuthnCredentialUsedFuncInvoked bool
 
-	NewScheduledQueryFunc        NewScheduledQueryFunc
-	NewScheduledQueryFuncInvoked bool
+	DeleteUserWebAuthnCredentialFunc        DeleteUserWebAuthnCredentialFunc
+	DeleteUserWebAuthnCredentialFuncInvoked bool
 
-	SaveScheduledQueryFunc        SaveScheduledQueryFunc
-	SaveScheduledQueryFuncInvoked bool
+	ReplaceUserMFARecoveryCodesFunc        ReplaceUserMFARecoveryCodesFunc
+	ReplaceUserMFARecoveryCodesFuncInvoked bool
 
-	DeleteScheduledQueryFunc        DeleteScheduledQueryFunc
-	DeleteScheduledQueryFuncInvoked bool
+	UseUserMFARecoveryCodeFunc        UseUserMFARecoveryCodeFunc
+	UseUserMFARecoveryCodeFuncInvoked bool
 
-	ScheduledQueryFunc        ScheduledQueryFunc
-	ScheduledQueryFuncInvoked bool
+	CountUserMFARecoveryCodesFunc        CountUserMFARecoveryCodesFunc
+	CountUserMFARecoveryCodesFuncInvoked bool
 
-	CleanupExpiredHostsFunc        CleanupExpiredHostsFunc
+	DeleteUserMFAFunc        DeleteUserMFAFunc
+	DeleteUserMFAFuncInvoked bool
+
+	NewMFAChallengeFunc        NewMFAChallengeFunc
+	NewMFAChallengeFuncInvoked bool
+
+	MFAChallengeFunc        MFAChallengeFunc
+	MFAChallengeFuncInvoked bool
+
+	RecordMFAChallengeAttemptFunc        RecordMFAChallengeAttemptFunc
+	RecordMFAChallengeAttemptFuncInvoked bool
+
+	DeleteMFAChallengeFunc        DeleteMFAChallengeFunc
+	DeleteMFAChallengeFuncInvoked bool
+
+	CleanupExpiredMFAChallengesFunc        CleanupExpiredMFAChallengesFunc
+	CleanupExpiredMFAChallengesFuncInvoked bool
+
+	NewSecretVariableFunc        NewSecretVariableFunc
+	NewSecretVariableFuncInvoked bool
+
+	UpsertSecretVariablesFunc        UpsertSecretVariablesFunc
+	UpsertSecretVariablesFuncInvoked bool
+
+	SecretVariableFunc        SecretVariableFunc
+	SecretVariableFuncInvoked bool
+
+	ListSecretVariablesFunc        ListSecretVariablesFunc
+	ListSecretVariablesFuncInvoked bool
+
+	GetSecretVariablesFunc        GetSecretVariablesFunc
+	GetSecretVariablesFuncInvoked bool
+
+	DeleteSecretVariableFunc        DeleteSecretVariableFunc
+	DeleteSecretVariableFuncInvoked bool
+
+	NewConfigVersionFunc        NewConfigVersionFunc
+	NewConfigVersionFuncInvoked bool
+
+	ConfigVersionFunc        ConfigVersionFunc
+	ConfigVersionFuncInvoked bool
+
+	ListConfigVersionsFunc        ListConfigVersionsFunc
+	ListConfigVersionsFuncInvoked bool
+
+	ListEmailTemplatesFunc        ListEmailTemplatesFunc
+	ListEmailTemplatesFuncInvoked bool
+
+	EmailTemplateFunc        EmailTemplateFunc
+	EmailTemplateFuncInvoked bool
+
+	SaveEmailTemplateFunc        SaveEmailTemplateFunc
+	SaveEmailTemplateFuncInvoked bool
+
+	DeleteEmailTemplateFunc        DeleteEmailTemplateFunc
+	DeleteEmailTemplateFuncInvoked bool
+
+	ListTranslationsFunc        ListTranslationsFunc
+	ListTranslationsFuncInvoked bool
+
+	TranslationsFunc        TranslationsFunc
+	TranslationsFuncInvoked bool
+
+	ApplyTranslationsFunc        ApplyTranslationsFunc
+	ApplyTranslationsFuncInvoked bool
+
+	DeleteTranslationsFunc        DeleteTranslationsFunc
+	DeleteTranslationsFuncInvoked bool
+
+	NewHostGroupFunc        NewHostGroupFunc
+	NewHostGroupFuncInvoked bool
+
+	HostGroupFunc        HostGroupFunc
+	HostGroupFuncInvoked bool
+
+	ListHostGroupsFunc        ListHostGroupsFunc
+	ListHostGroupsFuncInvoked bool
+
+	SaveHostGroupFunc        SaveHostGroupFunc
+	SaveHostGroupFuncInvoked bool
+
+	DeleteHostGroupFunc        DeleteHostGroupFunc
+	DeleteHostGroupFuncInvoked bool
+
+	AddHostsToHostGroupFunc        AddHostsToHostGroupFunc
+	AddHostsToHostGroupFuncInvoked bool
+
+	RemoveHostsFromHostGroupFunc        RemoveHostsFromHostGroupFunc
+	RemoveHostsFromHostGroupFuncInvoked bool
+
+	GetHostCustomFieldsFunc        GetHostCustomFieldsFunc
+	GetHostCustomFieldsFuncInvoked bool
+
+	UpdateHostCustomFieldsFunc        UpdateHostCustomFieldsFunc
+	UpdateHostCustomFieldsFuncInvoked bool
+
+	DeleteHostCustomFieldValuesNotInFunc        DeleteHostCustomFieldValuesNotInFunc
+	DeleteHostCustomFieldValuesNotInFuncInvoked bool
+
+	ListHostsForWarrantyLookupFunc        ListHostsForWarrantyLookupFunc
+	ListHostsForWarrantyLookupFuncInvoked bool
+
+	UpsertHostWarrantiesFunc        UpsertHostWarrantiesFunc
+	UpsertHostWarrantiesFuncInvoked bool
+
+	ListHostHealthScoreInputsFunc        ListHostHealthScoreInputsFunc
+	ListHostHealthScoreInputsFuncInvoked bool
+
+	UpsertHostHealthScoresFunc        UpsertHostHealthScoresFunc
+	UpsertHostHealthScoresFuncInvoked bool
+
+	GetHostHealthScoresFunc        GetHostHealthScoresFunc
+	GetHostHealthScoresFuncInvoked bool
+
+	ListHostsPendingApprovalFunc        ListHostsPendingApprovalFunc
+	ListHostsPendingApprovalFuncInvoked bool
+
+	ApproveHostEnrollmentFunc        ApproveHostEnrollmentFunc
+	ApproveHostEnrollmentFuncInvoked bool
+
+	ListDuplicateHostsFunc        ListDuplicateHostsFunc
+	ListDuplicateHostsFuncInvoked bool
+
+	MergeHostsFunc        MergeHostsFunc
+	MergeHostsFuncInvoked bool
+
+	NewTenantFunc        NewTenantFunc
+	NewTenantFuncInvoked bool
+
+	TenantFunc        TenantFunc
+	TenantFuncInvoked bool
+
+	ListTenantsFunc        ListTenantsFunc
+	ListTenantsFuncInvoked bool
+
+	SaveTenantFunc        SaveTenantFunc
+	SaveTenantFuncInvoked bool
+
+	DeleteTenantFunc        DeleteTenantFunc
+	DeleteTenantFuncInvoked bool
+
+	SetTeamsTenantFunc        SetTeamsTenantFunc
+	SetTeamsTenantFuncInvoked bool
+
+	SetUsersTenantFunc        SetUsersTenantFunc
+	SetUsersTenantFuncInvoked bool
+
+	TenantAppConfigFunc        TenantAppConfigFunc
+	TenantAppConfigFuncInvoked bool
+
+	SetTenantMDMAssetsFunc        SetTenantMDMAssetsFunc
+	SetTenantMDMAssetsFuncInvoked bool
+
+	GetTenantMDMAssetsFunc        GetTenantMDMAssetsFunc
+	GetTenantMDMAssetsFuncInvoked bool
+
+	RotateTenantEncryptionKeyFunc        RotateTenantEncryptionKeyFunc
+	RotateTenantEncryptionKeyFuncInvoked bool
+
+	NewDataExportFunc        NewDataExportFunc
+	NewDataExportFuncInvoked bool
+
+	DataExportFunc        DataExportFunc
+	DataExportFuncInvoked bool
+
+	DataExportContentFunc        DataExportContentFunc
+	DataExportContentFuncInvoked bool
+
+	ListPendingDataExportsFunc        ListPendingDataExportsFunc
+	ListPendingDataExportsFuncInvoked bool
+
+	CompleteDataExportFunc        CompleteDataExportFunc
+	CompleteDataExportFuncInvoked bool
+
+	CleanupDataExportsFunc        CleanupDataExportsFunc
+	CleanupDataExportsFuncInvoked bool
+
+	NewAppConfigFunc        NewAppConfigFunc
+	NewAppConfigFuncInvoked bool
+
+	AppConfigFunc        AppConfigFunc
+	AppConfigFuncInvoked bool
+
+	SaveAppConfigFunc        SaveAppConfigFunc
+	SaveAppConfigFuncInvoked bool
+
+	GetEnrollSecretsFunc        GetEnrollSecretsFunc
+	GetEnrollSecretsFuncInvoked bool
+
+	ApplyEnrollSecretsFunc        ApplyEnrollSecretsFunc
+	ApplyEnrollSecretsFuncInvoked bool
+
+	AggregateEnrollSecretPerTeamFunc        AggregateEnrollSecretPerTeamFunc
+	AggregateEnrollSecretPerTeamFuncInvoked bool
+
+	NewInviteFunc        NewInviteFunc
+	NewInviteFuncInvoked bool
+
+	ListInvitesFunc        ListInvitesFunc
+	ListInvitesFuncInvoked bool
+
+	InviteFunc        InviteFunc
+	InviteFuncInvoked bool
+
+	InviteByEmailFunc        InviteByEmailFunc
+	InviteByEmailFuncInvoked bool
+
+	InviteByTokenFunc        InviteByTokenFunc
+	InviteByTokenFuncInvoked bool
+
+	DeleteInviteFunc        DeleteInviteFunc
+	DeleteInviteFuncInvoked bool
+
+	UpdateInviteFunc        UpdateInviteFunc
+	UpdateInviteFuncInvoked bool
+
+	ListScheduledQueriesInPackWithStatsFunc        ListScheduledQueriesInPackWithStatsFunc
+	ListScheduledQueriesInPackWithStatsFuncInvoked bool
+
+	NewScheduledQueryFunc        NewScheduledQueryFunc
+	NewScheduledQueryFuncInvoked bool
+
+	SaveScheduledQueryFunc        SaveScheduledQueryFunc
+	SaveScheduledQueryFuncInvoked bool
+
+	DeleteScheduledQueryFunc        DeleteScheduledQueryFunc
+	DeleteScheduledQueryFuncInvoked bool
+
+	ScheduledQueryFunc        ScheduledQueryFunc
+	ScheduledQueryFuncInvoked bool
+
+	CleanupExpiredHostsFunc        CleanupExpiredHostsFunc
 	CleanupExpiredHostsFuncInvoked bool
 
 	ScheduledQueryIDsByNameFunc        ScheduledQueryIDsByNameFunc
@@ -1526,6 +2200,21 @@ type DataStore struct {
 	IsExecutionPendingForHostFunc        IsExecutionPendingForHostFunc
 	IsExecutionPendingForHostFuncInvoked bool
 
+	NewAdminNotificationFunc        NewAdminNotificationFunc
+	NewAdminNotificationFuncInvoked bool
+
+	ListAdminNotificationsFunc        ListAdminNotificationsFunc
+	ListAdminNotificationsFuncInvoked bool
+
+	CountUnreadAdminNotificationsFunc        CountUnreadAdminNotificationsFunc
+	CountUnreadAdminNotificationsFuncInvoked bool
+
+	MarkAdminNotificationsReadFunc        MarkAdminNotificationsReadFunc
+	MarkAdminNotificationsReadFuncInvoked bool
+
+	DeleteAdminNotificationFunc        DeleteAdminNotificationFunc
+	DeleteAdminNotificationFuncInvoked bool
+
 	ShouldSendStatisticsFunc        ShouldSendStatisticsFunc
 	ShouldSendStatisticsFuncInvoked bool
 
@@ -1535,6 +2224,18 @@ type DataStore struct {
 	CleanupStatisticsFunc        CleanupStatisticsFunc
 	CleanupStatisticsFuncInvoked bool
 
+	IncrementUsageAnalyticsAPIRequestsFunc        IncrementUsageAnalyticsAPIRequestsFunc
+	IncrementUsageAnalyticsAPIRequestsFuncInvoked bool
+
+	RecordUsageAnalyticsHostCountFunc        RecordUsageAnalyticsHostCountFunc
+	RecordUsageAnalyticsHostCountFuncInvoked bool
+
+	CleanupUsageAnalyticsFunc        CleanupUsageAnalyticsFunc
+	CleanupUsageAnalyticsFuncInvoked bool
+
+	GetUsageAnalyticsDashboardFunc        GetUsageAnalyticsDashboardFunc
+	GetUsageAnalyticsDashboardFuncInvoked bool
+
 	ApplyPolicySpecsFunc        ApplyPolicySpecsFunc
 	ApplyPolicySpecsFuncInvoked bool
 
@@ -1562,6 +2263,15 @@ type DataStore struct {
 	UpdateHostPolicyCountsFunc        UpdateHostPolicyCountsFunc
 	UpdateHostPolicyCountsFuncInvoked bool
 
+	RecordPolicyComplianceHistoryFunc        RecordPolicyComplianceHistoryFunc
+	RecordPolicyComplianceHistoryFuncInvoked bool
+
+	CleanupPolicyComplianceHistoryFunc        CleanupPolicyComplianceHistoryFunc
+	CleanupPolicyComplianceHistoryFuncInvoked bool
+
+	GetPolicyComplianceTrendsFunc        GetPolicyComplianceTrendsFunc
+	GetPolicyComplianceTrendsFuncInvoked bool
+
 	PolicyQueriesForHostFunc        PolicyQueriesForHostFunc
 	PolicyQueriesForHostFuncInvoked bool
 
@@ -1592,6 +2302,9 @@ type DataStore struct {
 	CountSoftwareFunc        CountSoftwareFunc
 	CountSoftwareFuncInvoked bool
 
+	StreamSoftwareFunc        StreamSoftwareFunc
+	StreamSoftwareFuncInvoked bool
+
 	DeleteSoftwareVulnerabilitiesFunc        DeleteSoftwareVulnerabilitiesFunc
 	DeleteSoftwareVulnerabilitiesFuncInvoked bool
 
@@ -1670,6 +2383,18 @@ type DataStore struct {
 	CleanupCronStatsFunc        CleanupCronStatsFunc
 	CleanupCronStatsFuncInvoked bool
 
+	ListLatestCronStatsFunc        ListLatestCronStatsFunc
+	ListLatestCronStatsFuncInvoked bool
+
+	IsCronSchedulePausedFunc        IsCronSchedulePausedFunc
+	IsCronSchedulePausedFuncInvoked bool
+
+	SetCronSchedulePausedFunc        SetCronSchedulePausedFunc
+	SetCronSchedulePausedFuncInvoked bool
+
+	ListPausedCronSchedulesFunc        ListPausedCronSchedulesFunc
+	ListPausedCronSchedulesFuncInvoked bool
+
 	UpdateQueryAggregatedStatsFunc        UpdateQueryAggregatedStatsFunc
 	UpdateQueryAggregatedStatsFuncInvoked bool
 
@@ -1703,6 +2428,15 @@ type DataStore struct {
 	UpdateHostSoftwareFunc        UpdateHostSoftwareFunc
 	UpdateHostSoftwareFuncInvoked bool
 
+	UpdateHostSoftwareFromSourceFunc        UpdateHostSoftwareFromSourceFunc
+	UpdateHostSoftwareFromSourceFuncInvoked bool
+
+	UpdateHostSoftwareUpdatedAtFunc        UpdateHostSoftwareUpdatedAtFunc
+	UpdateHostSoftwareUpdatedAtFuncInvoked bool
+
+	DeleteHostSoftwareByBundleIdentifierFunc        DeleteHostSoftwareByBundleIdentifierFunc
+	DeleteHostSoftwareByBundleIdentifierFuncInvoked bool
+
 	UpdateHostSoftwareInstalledPathsFunc        UpdateHostSoftwareInstalledPathsFunc
 	UpdateHostSoftwareInstalledPathsFuncInvoked bool
 
@@ -1760,18 +2494,48 @@ type DataStore struct {
 	GetHostDiskEncryptionKeyFunc        GetHostDiskEncryptionKeyFunc
 	GetHostDiskEncryptionKeyFuncInvoked bool
 
+	SetHostDiskEncryptionKeyRotationPendingFunc        SetHostDiskEncryptionKeyRotationPendingFunc
+	SetHostDiskEncryptionKeyRotationPendingFuncInvoked bool
+
+	UpdateHostDiskEncryptionKeyFromRotateResultFunc        UpdateHostDiskEncryptionKeyFromRotateResultFunc
+	UpdateHostDiskEncryptionKeyFromRotateResultFuncInvoked bool
+
+	SaveLUKSDiskEncryptionKeyFunc        SaveLUKSDiskEncryptionKeyFunc
+	SaveLUKSDiskEncryptionKeyFuncInvoked bool
+
+	GetHostLUKSDiskEncryptionKeyFunc        GetHostLUKSDiskEncryptionKeyFunc
+	GetHostLUKSDiskEncryptionKeyFuncInvoked bool
+
+	SetHostLinuxDiskEncryptionEnforcementFunc        SetHostLinuxDiskEncryptionEnforcementFunc
+	SetHostLinuxDiskEncryptionEnforcementFuncInvoked bool
+
 	SetDiskEncryptionResetStatusFunc        SetDiskEncryptionResetStatusFunc
 	SetDiskEncryptionResetStatusFuncInvoked bool
 
 	GetHostCertAssociationsToExpireFunc        GetHostCertAssociationsToExpireFunc
 	GetHostCertAssociationsToExpireFuncInvoked bool
 
+	GetHostSCEPIdentityAssociationFunc        GetHostSCEPIdentityAssociationFunc
+	GetHostSCEPIdentityAssociationFuncInvoked bool
+
 	SetCommandForPendingSCEPRenewalFunc        SetCommandForPendingSCEPRenewalFunc
 	SetCommandForPendingSCEPRenewalFuncInvoked bool
 
 	CleanSCEPRenewRefsFunc        CleanSCEPRenewRefsFunc
 	CleanSCEPRenewRefsFuncInvoked bool
 
+	GetMDMAppleConfigProfileByHostCommandUUIDFunc        GetMDMAppleConfigProfileByHostCommandUUIDFunc
+	GetMDMAppleConfigProfileByHostCommandUUIDFuncInvoked bool
+
+	RecordHostMDMAppleProfileCertificateFunc        RecordHostMDMAppleProfileCertificateFunc
+	RecordHostMDMAppleProfileCertificateFuncInvoked bool
+
+	GetHostMDMAppleProfileCertificatesToRenewFunc        GetHostMDMAppleProfileCertificatesToRenewFunc
+	GetHostMDMAppleProfileCertificatesToRenewFuncInvoked bool
+
+	SetCommandForPendingProfileCertificateRenewalFunc        SetCommandForPendingProfileCertificateRenewalFunc
+	SetCommandForPendingProfileCertificateRenewalFuncInvoked bool
+
 	UpdateHostMDMProfilesVerificationFunc        UpdateHostMDMProfilesVerificationFunc
 	UpdateHostMDMProfilesVerificationFuncInvoked bool
 
@@ -1796,6 +2560,21 @@ type DataStore struct {
 	ReplaceHostBatteriesFunc        ReplaceHostBatteriesFunc
 	ReplaceHostBatteriesFuncInvoked bool
 
+	ReplaceHostCertificatesFunc        ReplaceHostCertificatesFunc
+	ReplaceHostCertificatesFuncInvoked bool
+
+	ListHostCertificatesFunc        ListHostCertificatesFunc
+	ListHostCertificatesFuncInvoked bool
+
+	SetOrUpdateHostCloudInstanceFunc        SetOrUpdateHostCloudInstanceFunc
+	SetOrUpdateHostCloudInstanceFuncInvoked bool
+
+	ListCertificateInventoryFunc        ListCertificateInventoryFunc
+	ListCertificateInventoryFuncInvoked bool
+
+	ListExpiringCertificatesFunc        ListExpiringCertificatesFunc
+	ListExpiringCertificatesFuncInvoked bool
+
 	VerifyEnrollSecretFunc        VerifyEnrollSecretFunc
 	VerifyEnrollSecretFuncInvoked bool
 
@@ -1805,6 +2584,18 @@ type DataStore struct {
 	EnrollOrbitFunc        EnrollOrbitFunc
 	EnrollOrbitFuncInvoked bool
 
+	GetHostEnrollmentCertificateBySHA256Func        GetHostEnrollmentCertificateBySHA256Func
+	GetHostEnrollmentCertificateBySHA256FuncInvoked bool
+
+	RecordHostEnrollmentCertificateFunc        RecordHostEnrollmentCertificateFunc
+	RecordHostEnrollmentCertificateFuncInvoked bool
+
+	ListHostEnrollmentCertificatesFunc        ListHostEnrollmentCertificatesFunc
+	ListHostEnrollmentCertificatesFuncInvoked bool
+
+	RevokeHostEnrollmentCertificatesFunc        RevokeHostEnrollmentCertificatesFunc
+	RevokeHostEnrollmentCertificatesFuncInvoked bool
+
 	SerialUpdateHostFunc        SerialUpdateHostFunc
 	SerialUpdateHostFuncInvoked bool
 
@@ -1823,6 +2614,33 @@ type DataStore struct {
 	ProcessListFunc        ProcessListFunc
 	ProcessListFuncInvoked bool
 
+	ListDatabaseMaintenanceRunsFunc        ListDatabaseMaintenanceRunsFunc
+	ListDatabaseMaintenanceRunsFuncInvoked bool
+
+	RecordDatabaseMaintenanceRunFunc        RecordDatabaseMaintenanceRunFunc
+	RecordDatabaseMaintenanceRunFuncInvoked bool
+
+	SnapshotTableSizesFunc        SnapshotTableSizesFunc
+	SnapshotTableSizesFuncInvoked bool
+
+	ListTableGrowthFunc        ListTableGrowthFunc
+	ListTableGrowthFuncInvoked bool
+
+	AnalyzeTablesFunc        AnalyzeTablesFunc
+	AnalyzeTablesFuncInvoked bool
+
+	OptimizeTablesFunc        OptimizeTablesFunc
+	OptimizeTablesFuncInvoked bool
+
+	CleanupExpiredSessionsFunc        CleanupExpiredSessionsFunc
+	CleanupExpiredSessionsFuncInvoked bool
+
+	CleanupExpiredInvitesFunc        CleanupExpiredInvitesFunc
+	CleanupExpiredInvitesFuncInvoked bool
+
+	CleanupOrphanedHostRowsFunc        CleanupOrphanedHostRowsFunc
+	CleanupOrphanedHostRowsFuncInvoked bool
+
 	ListWindowsUpdatesByHostIDFunc        ListWindowsUpdatesByHostIDFunc
 	ListWindowsUpdatesByHostIDFuncInvoked bool
 
@@ -1859,6 +2677,15 @@ type DataStore struct {
 	UpdateVulnerabilityHostCountsFunc        UpdateVulnerabilityHostCountsFunc
 	UpdateVulnerabilityHostCountsFuncInvoked bool
 
+	UpdateHostCVEDetectionsFunc        UpdateHostCVEDetectionsFunc
+	UpdateHostCVEDetectionsFuncInvoked bool
+
+	ListVulnerabilitySLABreachesFunc        ListVulnerabilitySLABreachesFunc
+	ListVulnerabilitySLABreachesFuncInvoked bool
+
+	MarkVulnerabilitySLABreachesNotifiedFunc        MarkVulnerabilitySLABreachesNotifiedFunc
+	MarkVulnerabilitySLABreachesNotifiedFuncInvoked bool
+
 	NewMDMAppleConfigProfileFunc        NewMDMAppleConfigProfileFunc
 	NewMDMAppleConfigProfileFuncInvoked bool
 
@@ -1967,12 +2794,21 @@ type DataStore struct {
 	GetNanoMDMEnrollmentFunc        GetNanoMDMEnrollmentFunc
 	GetNanoMDMEnrollmentFuncInvoked bool
 
+	ListNanoMDMUserEnrollmentDeviceIDsFunc        ListNanoMDMUserEnrollmentDeviceIDsFunc
+	ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked bool
+
 	IncreasePolicyAutomationIterationFunc        IncreasePolicyAutomationIterationFunc
 	IncreasePolicyAutomationIterationFuncInvoked bool
 
 	OutdatedAutomationBatchFunc        OutdatedAutomationBatchFunc
 	OutdatedAutomationBatchFuncInvoked bool
 
+	ListPolicyFailingHostsFunc        ListPolicyFailingHostsFunc
+	ListPolicyFailingHostsFuncInvoked bool
+
+	StreamPolicyMembershipFunc        StreamPolicyMembershipFunc
+	StreamPolicyMembershipFuncInvoked bool
+
 	ListMDMAppleProfilesToInstallFunc        ListMDMAppleProfilesToInstallFunc
 	ListMDMAppleProfilesToInstallFuncInvoked bool
 
@@ -1994,6 +2830,15 @@ type DataStore struct {
 	GetMDMAppleCommandRequestTypeFunc        GetMDMAppleCommandRequestTypeFunc
 	GetMDMAppleCommandRequestTypeFuncInvoked bool
 
+	ListIOSAndIPadOSToRefetchFunc        ListIOSAndIPadOSToRefetchFunc
+	ListIOSAndIPadOSToRefetchFuncInvoked bool
+
+	ListAppleHostsForMDMSoftwareRefetchFunc        ListAppleHostsForMDMSoftwareRefetchFunc
+	ListAppleHostsForMDMSoftwareRefetchFuncInvoked bool
+
+	ListHostsWithNotNowMDMAppleCommandsFunc        ListHostsWithNotNowMDMAppleCommandsFunc
+	ListHostsWithNotNowMDMAppleCommandsFuncInvoked bool
+
 	GetMDMAppleProfilesSummaryFunc        GetMDMAppleProfilesSummaryFunc
 	GetMDMAppleProfilesSummaryFuncInvoked bool
 
@@ -2033,6 +2878,78 @@ type DataStore struct {
 	GetHostMDMMacOSSetupFunc        GetHostMDMMacOSSetupFunc
 	GetHostMDMMacOSSetupFuncInvoked bool
 
+	RecordHostFleetdInstallFunc        RecordHostFleetdInstallFunc
+	RecordHostFleetdInstallFuncInvoked bool
+
+	GetHostMDMFleetdInstallFunc        GetHostMDMFleetdInstallFunc
+	GetHostMDMFleetdInstallFuncInvoked bool
+
+	SetOrUpdateHostMDMAppleSecurityInfoFunc        SetOrUpdateHostMDMAppleSecurityInfoFunc
+	SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked bool
+
+	GetHostMDMAppleSecurityInfoFunc        GetHostMDMAppleSecurityInfoFunc
+	GetHostMDMAppleSecurityInfoFuncInvoked bool
+
+	NewHostLostModeFunc        NewHostLostModeFunc
+	NewHostLostModeFuncInvoked bool
+
+	GetHostLostModeFunc        GetHostLostModeFunc
+	GetHostLostModeFuncInvoked bool
+
+	SetHostLostModePendingDisableFunc        SetHostLostModePendingDisableFunc
+	SetHostLostModePendingDisableFuncInvoked bool
+
+	UpdateHostLostModeFromAppleMDMResultFunc        UpdateHostLostModeFromAppleMDMResultFunc
+	UpdateHostLostModeFromAppleMDMResultFuncInvoked bool
+
+	NewHostLocationFunc        NewHostLocationFunc
+	NewHostLocationFuncInvoked bool
+
+	GetHostLastLocationFunc        GetHostLastLocationFunc
+	GetHostLastLocationFuncInvoked bool
+
+	SetHostMDMAppleActivationLockBypassCodeFunc        SetHostMDMAppleActivationLockBypassCodeFunc
+	SetHostMDMAppleActivationLockBypassCodeFuncInvoked bool
+
+	GetHostMDMAppleActivationLockBypassCodeFunc        GetHostMDMAppleActivationLockBypassCodeFunc
+	GetHostMDMAppleActivationLockBypassCodeFuncInvoked bool
+
+	SetHostMDMAppleActivationLockBypassCodePendingClearFunc        SetHostMDMAppleActivationLockBypassCodePendingClearFunc
+	SetHostMDMAppleActivationLockBypassCodePendingClearFuncInvoked bool
+
+	UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFunc        UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFunc
+	UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFuncInvoked bool
+
+	ListHostsForAppleRecoveryLockRotationFunc        ListHostsForAppleRecoveryLockRotationFunc
+	ListHostsForAppleRecoveryLockRotationFuncInvoked bool
+
+	SetHostMDMAppleRecoveryLockPendingFunc        SetHostMDMAppleRecoveryLockPendingFunc
+	SetHostMDMAppleRecoveryLockPendingFuncInvoked bool
+
+	UpdateHostMDMAppleRecoveryLockFromSetResultFunc        UpdateHostMDMAppleRecoveryLockFromSetResultFunc
+	UpdateHostMDMAppleRecoveryLockFromSetResultFuncInvoked bool
+
+	SetHostMDMAppleRecoveryLockPendingVerifyFunc        SetHostMDMAppleRecoveryLockPendingVerifyFunc
+	SetHostMDMAppleRecoveryLockPendingVerifyFuncInvoked bool
+
+	UpdateHostMDMAppleRecoveryLockFromVerifyResultFunc        UpdateHostMDMAppleRecoveryLockFromVerifyResultFunc
+	UpdateHostMDMAppleRecoveryLockFromVerifyResultFuncInvoked bool
+
+	GetHostMDMAppleRecoveryLockPasswordFunc        GetHostMDMAppleRecoveryLockPasswordFunc
+	GetHostMDMAppleRecoveryLockPasswordFuncInvoked bool
+
+	UpdateHostMDMAppleFirmwarePasswordFromSetResultFunc        UpdateHostMDMAppleFirmwarePasswordFromSetResultFunc
+	UpdateHostMDMAppleFirmwarePasswordFromSetResultFuncInvoked bool
+
+	SetHostMDMAppleFirmwarePasswordPendingVerifyFunc        SetHostMDMAppleFirmwarePasswordPendingVerifyFunc
+	SetHostMDMAppleFirmwarePasswordPendingVerifyFuncInvoked bool
+
+	UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFunc        UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFunc
+	UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFuncInvoked bool
+
+	GetHostMDMAppleFirmwarePasswordFunc        GetHostMDMAppleFirmwarePasswordFunc
+	GetHostMDMAppleFirmwarePasswordFuncInvoked bool
+
 	MDMGetEULAMetadataFunc        MDMGetEULAMetadataFunc
 	MDMGetEULAMetadataFuncInvoked bool
 
@@ -2153,9 +3070,45 @@ type DataStore struct {
 	ListMDMConfigProfilesFunc        ListMDMConfigProfilesFunc
 	ListMDMConfigProfilesFuncInvoked bool
 
+	PreviewMDMProfileTargetsFunc        PreviewMDMProfileTargetsFunc
+	PreviewMDMProfileTargetsFuncInvoked bool
+
 	ResendHostMDMProfileFunc        ResendHostMDMProfileFunc
 	ResendHostMDMProfileFuncInvoked bool
 
+	NewMDMProfileRedeliveryCampaignFunc        NewMDMProfileRedeliveryCampaignFunc
+	NewMDMProfileRedeliveryCampaignFuncInvoked bool
+
+	MDMProfileRedeliveryCampaignFunc        MDMProfileRedeliveryCampaignFunc
+	MDMProfileRedeliveryCampaignFuncInvoked bool
+
+	ListMDMProfileRedeliveryCampaignsFunc        ListMDMProfileRedeliveryCampaignsFunc
+	ListMDMProfileRedeliveryCampaignsFuncInvoked bool
+
+	ListRunningMDMProfileRedeliveryCampaignsFunc        ListRunningMDMProfileRedeliveryCampaignsFunc
+	ListRunningMDMProfileRedeliveryCampaignsFuncInvoked bool
+
+	UpdateMDMProfileRedeliveryCampaignFunc        UpdateMDMProfileRedeliveryCampaignFunc
+	UpdateMDMProfileRedeliveryCampaignFuncInvoked bool
+
+	RedeliverMDMProfileCampaignBatchFunc        RedeliverMDMProfileCampaignBatchFunc
+	RedeliverMDMProfileCampaignBatchFuncInvoked bool
+
+	GetMDMProfileRolloutFunc        GetMDMProfileRolloutFunc
+	GetMDMProfileRolloutFuncInvoked bool
+
+	ListActiveMDMProfileRolloutsFunc        ListActiveMDMProfileRolloutsFunc
+	ListActiveMDMProfileRolloutsFuncInvoked bool
+
+	GetMDMProfileRolloutResultsFunc        GetMDMProfileRolloutResultsFunc
+	GetMDMProfileRolloutResultsFuncInvoked bool
+
+	UpdateMDMProfileRolloutStatusFunc        UpdateMDMProfileRolloutStatusFunc
+	UpdateMDMProfileRolloutStatusFuncInvoked bool
+
+	CleanupMDMProfileRolloutsFunc        CleanupMDMProfileRolloutsFunc
+	CleanupMDMProfileRolloutsFuncInvoked bool
+
 	GetHostMDMProfileInstallStatusFunc        GetHostMDMProfileInstallStatusFunc
 	GetHostMDMProfileInstallStatusFuncInvoked bool
 
@@ -2168,6 +3121,9 @@ type DataStore struct {
 	GetMDMWindowsBitLockerSummaryFunc        GetMDMWindowsBitLockerSummaryFunc
 	GetMDMWindowsBitLockerSummaryFuncInvoked bool
 
+	GetMDMLinuxDiskEncryptionSummaryFunc        GetMDMLinuxDiskEncryptionSummaryFunc
+	GetMDMLinuxDiskEncryptionSummaryFuncInvoked bool
+
 	GetMDMWindowsBitLockerStatusFunc        GetMDMWindowsBitLockerStatusFunc
 	GetMDMWindowsBitLockerStatusFuncInvoked bool
 
@@ -2204,6 +3160,75 @@ type DataStore struct {
 	SetOrUpdateMDMAppleDeclarationFunc        SetOrUpdateMDMAppleDeclarationFunc
 	SetOrUpdateMDMAppleDeclarationFuncInvoked bool
 
+	NewHostLogCollectionFunc        NewHostLogCollectionFunc
+	NewHostLogCollectionFuncInvoked bool
+
+	GetHostLogCollectionFunc        GetHostLogCollectionFunc
+	GetHostLogCollectionFuncInvoked bool
+
+	GetHostLogCollectionDataFunc        GetHostLogCollectionDataFunc
+	GetHostLogCollectionDataFuncInvoked bool
+
+	ListHostLogCollectionsFunc        ListHostLogCollectionsFunc
+	ListHostLogCollectionsFuncInvoked bool
+
+	ListPendingHostLogCollectionsFunc        ListPendingHostLogCollectionsFunc
+	ListPendingHostLogCollectionsFuncInvoked bool
+
+	SetHostLogCollectionResultFunc        SetHostLogCollectionResultFunc
+	SetHostLogCollectionResultFuncInvoked bool
+
+	NewHostFileRetrievalFunc        NewHostFileRetrievalFunc
+	NewHostFileRetrievalFuncInvoked bool
+
+	GetHostFileRetrievalFunc        GetHostFileRetrievalFunc
+	GetHostFileRetrievalFuncInvoked bool
+
+	GetHostFileRetrievalDataFunc        GetHostFileRetrievalDataFunc
+	GetHostFileRetrievalDataFuncInvoked bool
+
+	ListHostFileRetrievalsFunc        ListHostFileRetrievalsFunc
+	ListHostFileRetrievalsFuncInvoked bool
+
+	ListPendingHostFileRetrievalsFunc        ListPendingHostFileRetrievalsFunc
+	ListPendingHostFileRetrievalsFuncInvoked bool
+
+	SetHostFileRetrievalResultFunc        SetHostFileRetrievalResultFunc
+	SetHostFileRetrievalResultFuncInvoked bool
+
+	NewHostPprofCaptureFunc        NewHostPprofCaptureFunc
+	NewHostPprofCaptureFuncInvoked bool
+
+	GetHostPprofCaptureFunc        GetHostPprofCaptureFunc
+	GetHostPprofCaptureFuncInvoked bool
+
+	GetHostPprofCaptureDataFunc        GetHostPprofCaptureDataFunc
+	GetHostPprofCaptureDataFuncInvoked bool
+
+	ListHostPprofCapturesFunc        ListHostPprofCapturesFunc
+	ListHostPprofCapturesFuncInvoked bool
+
+	ListPendingHostPprofCapturesFunc        ListPendingHostPprofCapturesFunc
+	ListPendingHostPprofCapturesFuncInvoked bool
+
+	SetHostPprofCaptureResultFunc        SetHostPprofCaptureResultFunc
+	SetHostPprofCaptureResultFuncInvoked bool
+
+	NewHostRemoteQueryFunc        NewHostRemoteQueryFunc
+	NewHostRemoteQueryFuncInvoked bool
+
+	GetHostRemoteQueryFunc        GetHostRemoteQueryFunc
+	GetHostRemoteQueryFuncInvoked bool
+
+	ListHostRemoteQueriesFunc        ListHostRemoteQueriesFunc
+	ListHostRemoteQueriesFuncInvoked bool
+
+	ListPendingHostRemoteQueriesFunc        ListPendingHostRemoteQueriesFunc
+	ListPendingHostRemoteQueriesFuncInvoked bool
+
+	SetHostRemoteQueryResultFunc        SetHostRemoteQueryResultFunc
+	SetHostRemoteQueryResultFuncInvoked bool
+
 	NewHostScriptExecutionRequestFunc        NewHostScriptExecutionRequestFunc
 	NewHostScriptExecutionRequestFuncInvoked bool
 
@@ -2240,6 +3265,36 @@ type DataStore struct {
 	BatchSetScriptsFunc        BatchSetScriptsFunc
 	BatchSetScriptsFuncInvoked bool
 
+	SetScriptSharedWithAllTeamsFunc        SetScriptSharedWithAllTeamsFunc
+	SetScriptSharedWithAllTeamsFuncInvoked bool
+
+	SetMDMConfigProfileSharedWithAllTeamsFunc        SetMDMConfigProfileSharedWithAllTeamsFunc
+	SetMDMConfigProfileSharedWithAllTeamsFuncInvoked bool
+
+	GetTeamSharedLibraryOptOutsFunc        GetTeamSharedLibraryOptOutsFunc
+	GetTeamSharedLibraryOptOutsFuncInvoked bool
+
+	SetTeamSharedLibraryOptOutsFunc        SetTeamSharedLibraryOptOutsFunc
+	SetTeamSharedLibraryOptOutsFuncInvoked bool
+
+	NewScriptScheduleFunc        NewScriptScheduleFunc
+	NewScriptScheduleFuncInvoked bool
+
+	ScriptScheduleFunc        ScriptScheduleFunc
+	ScriptScheduleFuncInvoked bool
+
+	ListScriptSchedulesFunc        ListScriptSchedulesFunc
+	ListScriptSchedulesFuncInvoked bool
+
+	DeleteScriptScheduleFunc        DeleteScriptScheduleFunc
+	DeleteScriptScheduleFuncInvoked bool
+
+	ListScriptScheduleHostsFunc        ListScriptScheduleHostsFunc
+	ListScriptScheduleHostsFuncInvoked bool
+
+	NewScheduledHostScriptExecutionRequestFunc        NewScheduledHostScriptExecutionRequestFunc
+	NewScheduledHostScriptExecutionRequestFuncInvoked bool
+
 	GetHostLockWipeStatusFunc        GetHostLockWipeStatusFunc
 	GetHostLockWipeStatusFuncInvoked bool
 
@@ -2255,6 +3310,15 @@ type DataStore struct {
 	CleanMacOSMDMLockFunc        CleanMacOSMDMLockFunc
 	CleanMacOSMDMLockFuncInvoked bool
 
+	NewHostLockWipeApprovalRequestFunc        NewHostLockWipeApprovalRequestFunc
+	NewHostLockWipeApprovalRequestFuncInvoked bool
+
+	GetHostLockWipeApprovalRequestFunc        GetHostLockWipeApprovalRequestFunc
+	GetHostLockWipeApprovalRequestFuncInvoked bool
+
+	ClaimHostLockWipeApprovalRequestFunc        ClaimHostLockWipeApprovalRequestFunc
+	ClaimHostLockWipeApprovalRequestFuncInvoked bool
+
 	CleanupUnusedScriptContentsFunc        CleanupUnusedScriptContentsFunc
 	CleanupUnusedScriptContentsFuncInvoked bool
 
@@ -2264,6 +3328,12 @@ type DataStore struct {
 	WipeHostViaScriptFunc        WipeHostViaScriptFunc
 	WipeHostViaScriptFuncInvoked bool
 
+	IsolateHostViaScriptFunc        IsolateHostViaScriptFunc
+	IsolateHostViaScriptFuncInvoked bool
+
+	UnisolateHostViaScriptFunc        UnisolateHostViaScriptFunc
+	UnisolateHostViaScriptFuncInvoked bool
+
 	WipeHostViaWindowsMDMFunc        WipeHostViaWindowsMDMFunc
 	WipeHostViaWindowsMDMFuncInvoked bool
 
@@ -2812,6 +3882,20 @@ func (s *DataStore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 	return s.ListHostsFunc(ctx, filter, opt)
 }
 
+func (s *DataStore) StreamHosts(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions, batchSize int, fn func([]*fleet.Host) error) error {
+	s.mu.Lock()
+	s.StreamHostsFuncInvoked = true
+	s.mu.Unlock()
+	return s.StreamHostsFunc(ctx, filter, opt, batchSize, fn)
+}
+
+func (s *DataStore) ListHostChanges(ctx context.Context, filter fleet.TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*fleet.HostChange, error) {
+	s.mu.Lock()
+	s.ListHostChangesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostChangesFunc(ctx, filter, afterUpdatedAt, afterID, limit)
+}
+
 func (s *DataStore) ListHostsLiteByUUIDs(ctx context.Context, filter fleet.TeamFilter, uuids []string) ([]*fleet.Host, error) {
 	s.mu.Lock()
 	s.ListHostsLiteByUUIDsFuncInvoked = true
@@ -2833,9 +3917,23 @@ func (s *DataStore) MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.Ti
 	return s.MarkHostsSeenFunc(ctx, hostIDs, t)
 }
 
-func (s *DataStore) SearchHosts(ctx context.Context, filter fleet.TeamFilter, query string, omit ...uint) ([]*fleet.Host, error) {
+func (s *DataStore) MarkHostsSeenByChannel(ctx context.Context, channel fleet.HostSeenChannel, hostIDs []uint, t time.Time) error {
 	s.mu.Lock()
-	s.SearchHostsFuncInvoked = true
+	s.MarkHostsSeenByChannelFuncInvoked = true
+	s.mu.Unlock()
+	return s.MarkHostsSeenByChannelFunc(ctx, channel, hostIDs, t)
+}
+
+func (s *DataStore) MarkHostMDMSeen(ctx context.Context, hostUUID string, t time.Time) error {
+	s.mu.Lock()
+	s.MarkHostMDMSeenFuncInvoked = true
+	s.mu.Unlock()
+	return s.MarkHostMDMSeenFunc(ctx, hostUUID, t)
+}
+
+func (s *DataStore) SearchHosts(ctx context.Context, filter fleet.TeamFilter, query string, omit ...uint) ([]*fleet.Host, error) {
+	s.mu.Lock()
+	s.SearchHostsFuncInvoked = true
 	s.mu.Unlock()
 	return s.SearchHostsFunc(ctx, filter, query, omit...)
 }
@@ -2875,6 +3973,13 @@ func (s *DataStore) HostIDsByName(ctx context.Context, filter fleet.TeamFilter,
 	return s.HostIDsByNameFunc(ctx, filter, hostnames)
 }
 
+func (s *DataStore) HostIDsByIdentifiers(ctx context.Context, filter fleet.TeamFilter, identifiers []string) ([]uint, error) {
+	s.mu.Lock()
+	s.HostIDsByIdentifiersFuncInvoked = true
+	s.mu.Unlock()
+	return s.HostIDsByIdentifiersFunc(ctx, filter, identifiers)
+}
+
 func (s *DataStore) HostIDsByOSID(ctx context.Context, osID uint, offset int, limit int) ([]uint, error) {
 	s.mu.Lock()
 	s.HostIDsByOSIDFuncInvoked = true
@@ -3183,11 +4288,11 @@ func (s *DataStore) ListSessionsForUser(ctx context.Context, id uint) ([]*fleet.
 	return s.ListSessionsForUserFunc(ctx, id)
 }
 
-func (s *DataStore) NewSession(ctx context.Context, userID uint, sessionKey string) (*fleet.Session, error) {
+func (s *DataStore) NewSession(ctx context.Context, userID uint, sessionKey string, ipAddress string, userAgent string) (*fleet.Session, error) {
 	s.mu.Lock()
 	s.NewSessionFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewSessionFunc(ctx, userID, sessionKey)
+	return s.NewSessionFunc(ctx, userID, sessionKey, ipAddress, userAgent)
 }
 
 func (s *DataStore) DestroySession(ctx context.Context, session *fleet.Session) error {
@@ -3211,557 +4316,1159 @@ func (s *DataStore) MarkSessionAccessed(ctx context.Context, session *fleet.Sess
 	return s.MarkSessionAccessedFunc(ctx, session)
 }
 
-func (s *DataStore) NewAppConfig(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error) {
+func (s *DataStore) NewAPIToken(ctx context.Context, token *fleet.APIToken, tokenHash string) (*fleet.APIToken, error) {
 	s.mu.Lock()
-	s.NewAppConfigFuncInvoked = true
+	s.NewAPITokenFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewAppConfigFunc(ctx, info)
+	return s.NewAPITokenFunc(ctx, token, tokenHash)
 }
 
-func (s *DataStore) AppConfig(ctx context.Context) (*fleet.AppConfig, error) {
+func (s *DataStore) APIToken(ctx context.Context, id uint) (*fleet.APIToken, error) {
 	s.mu.Lock()
-	s.AppConfigFuncInvoked = true
+	s.APITokenFuncInvoked = true
 	s.mu.Unlock()
-	return s.AppConfigFunc(ctx)
+	return s.APITokenFunc(ctx, id)
 }
 
-func (s *DataStore) SaveAppConfig(ctx context.Context, info *fleet.AppConfig) error {
+func (s *DataStore) APITokenByHash(ctx context.Context, tokenHash string) (*fleet.APIToken, error) {
 	s.mu.Lock()
-	s.SaveAppConfigFuncInvoked = true
+	s.APITokenByHashFuncInvoked = true
 	s.mu.Unlock()
-	return s.SaveAppConfigFunc(ctx, info)
+	return s.APITokenByHashFunc(ctx, tokenHash)
 }
 
-func (s *DataStore) GetEnrollSecrets(ctx context.Context, teamID *uint) ([]*fleet.EnrollSecret, error) {
+func (s *DataStore) ListAPITokens(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error) {
 	s.mu.Lock()
-	s.GetEnrollSecretsFuncInvoked = true
+	s.ListAPITokensFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetEnrollSecretsFunc(ctx, teamID)
+	return s.ListAPITokensFunc(ctx, teamID)
 }
 
-func (s *DataStore) ApplyEnrollSecrets(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
+func (s *DataStore) DeleteAPIToken(ctx context.Context, id uint) error {
 	s.mu.Lock()
-	s.ApplyEnrollSecretsFuncInvoked = true
+	s.DeleteAPITokenFuncInvoked = true
 	s.mu.Unlock()
-	return s.ApplyEnrollSecretsFunc(ctx, teamID, secrets)
+	return s.DeleteAPITokenFunc(ctx, id)
 }
 
-func (s *DataStore) AggregateEnrollSecretPerTeam(ctx context.Context) ([]*fleet.EnrollSecret, error) {
+func (s *DataStore) MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error {
 	s.mu.Lock()
-	s.AggregateEnrollSecretPerTeamFuncInvoked = true
+	s.MarkAPITokenUsedFuncInvoked = true
 	s.mu.Unlock()
-	return s.AggregateEnrollSecretPerTeamFunc(ctx)
+	return s.MarkAPITokenUsedFunc(ctx, id, usedAt)
 }
 
-func (s *DataStore) NewInvite(ctx context.Context, i *fleet.Invite) (*fleet.Invite, error) {
+func (s *DataStore) SetUserTOTPSecret(ctx context.Context, userID uint, secret string) error {
 	s.mu.Lock()
-	s.NewInviteFuncInvoked = true
+	s.SetUserTOTPSecretFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewInviteFunc(ctx, i)
+	return s.SetUserTOTPSecretFunc(ctx, userID, secret)
 }
 
-func (s *DataStore) ListInvites(ctx context.Context, opt fleet.ListOptions) ([]*fleet.Invite, error) {
+func (s *DataStore) UserTOTPSecret(ctx context.Context, userID uint) (*fleet.UserTOTPSecret, error) {
 	s.mu.Lock()
-	s.ListInvitesFuncInvoked = true
+	s.UserTOTPSecretFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListInvitesFunc(ctx, opt)
+	return s.UserTOTPSecretFunc(ctx, userID)
 }
 
-func (s *DataStore) Invite(ctx context.Context, id uint) (*fleet.Invite, error) {
+func (s *DataStore) ConfirmUserTOTPSecret(ctx context.Context, userID uint, step int64) error {
 	s.mu.Lock()
-	s.InviteFuncInvoked = true
+	s.ConfirmUserTOTPSecretFuncInvoked = true
 	s.mu.Unlock()
-	return s.InviteFunc(ctx, id)
+	return s.ConfirmUserTOTPSecretFunc(ctx, userID, step)
 }
 
-func (s *DataStore) InviteByEmail(ctx context.Context, email string) (*fleet.Invite, error) {
+func (s *DataStore) MarkUserTOTPStepUsed(ctx context.Context, userID uint, step int64) (bool, error) {
 	s.mu.Lock()
-	s.InviteByEmailFuncInvoked = true
+	s.MarkUserTOTPStepUsedFuncInvoked = true
 	s.mu.Unlock()
-	return s.InviteByEmailFunc(ctx, email)
+	return s.MarkUserTOTPStepUsedFunc(ctx, userID, step)
 }
 
-func (s *DataStore) InviteByToken(ctx context.Context, token string) (*fleet.Invite, error) {
+func (s *DataStore) DeleteUserTOTPSecret(ctx context.Context, userID uint) error {
 	s.mu.Lock()
-	s.InviteByTokenFuncInvoked = true
+	s.DeleteUserTOTPSecretFuncInvoked = true
 	s.mu.Unlock()
-	return s.InviteByTokenFunc(ctx, token)
+	return s.DeleteUserTOTPSecretFunc(ctx, userID)
 }
 
-func (s *DataStore) DeleteInvite(ctx context.Context, id uint) error {
+func (s *DataStore) NewUserWebAuthnCredential(ctx context.Context, cred *fleet.UserWebAuthnCredential) (*fleet.UserWebAuthnCredential, error) {
 	s.mu.Lock()
-	s.DeleteInviteFuncInvoked = true
+	s.NewUserWebAuthnCredentialFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteInviteFunc(ctx, id)
+	return s.NewUserWebAuthnCredentialFunc(ctx, cred)
 }
 
-func (s *DataStore) UpdateInvite(ctx context.Context, id uint, i *fleet.Invite) (*fleet.Invite, error) {
+func (s *DataStore) ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*fleet.UserWebAuthnCredential, error) {
 	s.mu.Lock()
-	s.UpdateInviteFuncInvoked = true
+	s.ListUserWebAuthnCredentialsFuncInvoked = true
 	s.mu.Unlock()
-	return s.UpdateInviteFunc(ctx, id, i)
+	return s.ListUserWebAuthnCredentialsFunc(ctx, userID)
 }
 
-func (s *DataStore) ListScheduledQueriesInPackWithStats(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.ScheduledQuery, error) {
+func (s *DataStore) MarkUserWebAuthnCredentialUsed(ctx context.Context, id uint, signCount uint32) error {
 	s.mu.Lock()
-	s.ListScheduledQueriesInPackWithStatsFuncInvoked = true
+	s.MarkUserWebAuthnCredentialUsedFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListScheduledQueriesInPackWithStatsFunc(ctx, id, opts)
+	return s.MarkUserWebAuthnCredentialUsedFunc(ctx, id, signCount)
 }
 
-func (s *DataStore) NewScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
+func (s *DataStore) DeleteUserWebAuthnCredential(ctx context.Context, userID uint, id uint) error {
 	s.mu.Lock()
-	s.NewScheduledQueryFuncInvoked = true
+	s.DeleteUserWebAuthnCredentialFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewScheduledQueryFunc(ctx, sq, opts...)
+	return s.DeleteUserWebAuthnCredentialFunc(ctx, userID, id)
 }
 
-func (s *DataStore) SaveScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+func (s *DataStore) ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
 	s.mu.Lock()
-	s.SaveScheduledQueryFuncInvoked = true
+	s.ReplaceUserMFARecoveryCodesFuncInvoked = true
 	s.mu.Unlock()
-	return s.SaveScheduledQueryFunc(ctx, sq)
+	return s.ReplaceUserMFARecoveryCodesFunc(ctx, userID, codeHashes)
 }
 
-func (s *DataStore) DeleteScheduledQuery(ctx context.Context, id uint) error {
+func (s *DataStore) UseUserMFARecoveryCode(ctx context.Context, userID uint, codeHash string) error {
 	s.mu.Lock()
-	s.DeleteScheduledQueryFuncInvoked = true
+	s.UseUserMFARecoveryCodeFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteScheduledQueryFunc(ctx, id)
+	return s.UseUserMFARecoveryCodeFunc(ctx, userID, codeHash)
 }
 
-func (s *DataStore) ScheduledQuery(ctx context.Context, id uint) (*fleet.ScheduledQuery, error) {
+func (s *DataStore) CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error) {
 	s.mu.Lock()
-	s.ScheduledQueryFuncInvoked = true
+	s.CountUserMFARecoveryCodesFuncInvoked = true
 	s.mu.Unlock()
-	return s.ScheduledQueryFunc(ctx, id)
+	return s.CountUserMFARecoveryCodesFunc(ctx, userID)
 }
 
-func (s *DataStore) CleanupExpiredHosts(ctx context.Context) ([]uint, error) {
+func (s *DataStore) DeleteUserMFA(ctx context.Context, userID uint) error {
 	s.mu.Lock()
-	s.CleanupExpiredHostsFuncInvoked = true
+	s.DeleteUserMFAFuncInvoked = true
 	s.mu.Unlock()
-	return s.CleanupExpiredHostsFunc(ctx)
+	return s.DeleteUserMFAFunc(ctx, userID)
 }
 
-func (s *DataStore) ScheduledQueryIDsByName(ctx context.Context, batchSize int, packAndSchedQueryNames ...[2]string) ([]uint, error) {
+func (s *DataStore) NewMFAChallenge(ctx context.Context, challenge *fleet.MFAChallenge) error {
 	s.mu.Lock()
-	s.ScheduledQueryIDsByNameFuncInvoked = true
+	s.NewMFAChallengeFuncInvoked = true
 	s.mu.Unlock()
-	return s.ScheduledQueryIDsByNameFunc(ctx, batchSize, packAndSchedQueryNames...)
+	return s.NewMFAChallengeFunc(ctx, challenge)
 }
 
-func (s *DataStore) QueryResultRows(ctx context.Context, queryID uint, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+func (s *DataStore) MFAChallenge(ctx context.Context, token string) (*fleet.MFAChallenge, error) {
 	s.mu.Lock()
-	s.QueryResultRowsFuncInvoked = true
+	s.MFAChallengeFuncInvoked = true
 	s.mu.Unlock()
-	return s.QueryResultRowsFunc(ctx, queryID, filter)
+	return s.MFAChallengeFunc(ctx, token)
 }
 
-func (s *DataStore) QueryResultRowsForHost(ctx context.Context, queryID uint, hostID uint) ([]*fleet.ScheduledQueryResultRow, error) {
+func (s *DataStore) RecordMFAChallengeAttempt(ctx context.Context, token string, maxAttempts int) (bool, error) {
 	s.mu.Lock()
-	s.QueryResultRowsForHostFuncInvoked = true
+	s.RecordMFAChallengeAttemptFuncInvoked = true
 	s.mu.Unlock()
-	return s.QueryResultRowsForHostFunc(ctx, queryID, hostID)
+	return s.RecordMFAChallengeAttemptFunc(ctx, token, maxAttempts)
 }
 
-func (s *DataStore) ResultCountForQuery(ctx context.Context, queryID uint) (int, error) {
+func (s *DataStore) DeleteMFAChallenge(ctx context.Context, token string) error {
 	s.mu.Lock()
-	s.ResultCountForQueryFuncInvoked = true
+	s.DeleteMFAChallengeFuncInvoked = true
 	s.mu.Unlock()
-	return s.ResultCountForQueryFunc(ctx, queryID)
+	return s.DeleteMFAChallengeFunc(ctx, token)
 }
 
-func (s *DataStore) ResultCountForQueryAndHost(ctx context.Context, queryID uint, hostID uint) (int, error) {
+func (s *DataStore) CleanupExpiredMFAChallenges(ctx context.Context) error {
 	s.mu.Lock()
-	s.ResultCountForQueryAndHostFuncInvoked = true
+	s.CleanupExpiredMFAChallengesFuncInvoked = true
 	s.mu.Unlock()
-	return s.ResultCountForQueryAndHostFunc(ctx, queryID, hostID)
+	return s.CleanupExpiredMFAChallengesFunc(ctx)
 }
 
-func (s *DataStore) OverwriteQueryResultRows(ctx context.Context, rows []*fleet.ScheduledQueryResultRow) error {
+func (s *DataStore) NewSecretVariable(ctx context.Context, secret fleet.SecretVariablePayload) (*fleet.SecretVariable, error) {
 	s.mu.Lock()
-	s.OverwriteQueryResultRowsFuncInvoked = true
+	s.NewSecretVariableFuncInvoked = true
 	s.mu.Unlock()
-	return s.OverwriteQueryResultRowsFunc(ctx, rows)
+	return s.NewSecretVariableFunc(ctx, secret)
 }
 
-func (s *DataStore) CleanupDiscardedQueryResults(ctx context.Context) error {
+func (s *DataStore) UpsertSecretVariables(ctx context.Context, secrets []fleet.SecretVariablePayload) error {
 	s.mu.Lock()
-	s.CleanupDiscardedQueryResultsFuncInvoked = true
+	s.UpsertSecretVariablesFuncInvoked = true
 	s.mu.Unlock()
-	return s.CleanupDiscardedQueryResultsFunc(ctx)
+	return s.UpsertSecretVariablesFunc(ctx, secrets)
 }
 
-func (s *DataStore) NewTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+func (s *DataStore) SecretVariable(ctx context.Context, id uint) (*fleet.SecretVariable, error) {
 	s.mu.Lock()
-	s.NewTeamFuncInvoked = true
+	s.SecretVariableFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewTeamFunc(ctx, team)
+	return s.SecretVariableFunc(ctx, id)
 }
 
-func (s *DataStore) SaveTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+func (s *DataStore) ListSecretVariables(ctx context.Context) ([]*fleet.SecretVariable, error) {
 	s.mu.Lock()
-	s.SaveTeamFuncInvoked = true
+	s.ListSecretVariablesFuncInvoked = true
 	s.mu.Unlock()
-	return s.SaveTeamFunc(ctx, team)
+	return s.ListSecretVariablesFunc(ctx)
 }
 
-func (s *DataStore) Team(ctx context.Context, tid uint) (*fleet.Team, error) {
+func (s *DataStore) GetSecretVariables(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
 	s.mu.Lock()
-	s.TeamFuncInvoked = true
+	s.GetSecretVariablesFuncInvoked = true
 	s.mu.Unlock()
-	return s.TeamFunc(ctx, tid)
+	return s.GetSecretVariablesFunc(ctx, names)
 }
 
-func (s *DataStore) DeleteTeam(ctx context.Context, tid uint) error {
+func (s *DataStore) DeleteSecretVariable(ctx context.Context, id uint) error {
 	s.mu.Lock()
-	s.DeleteTeamFuncInvoked = true
+	s.DeleteSecretVariableFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteTeamFunc(ctx, tid)
+	return s.DeleteSecretVariableFunc(ctx, id)
 }
 
-func (s *DataStore) TeamByName(ctx context.Context, name string) (*fleet.Team, error) {
+func (s *DataStore) NewConfigVersion(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
 	s.mu.Lock()
-	s.TeamByNameFuncInvoked = true
+	s.NewConfigVersionFuncInvoked = true
 	s.mu.Unlock()
-	return s.TeamByNameFunc(ctx, name)
+	return s.NewConfigVersionFunc(ctx, teamID, author)
 }
 
-func (s *DataStore) ListTeams(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Team, error) {
+func (s *DataStore) ConfigVersion(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
 	s.mu.Lock()
-	s.ListTeamsFuncInvoked = true
+	s.ConfigVersionFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListTeamsFunc(ctx, filter, opt)
+	return s.ConfigVersionFunc(ctx, id)
 }
 
-func (s *DataStore) TeamsSummary(ctx context.Context) ([]*fleet.TeamSummary, error) {
+func (s *DataStore) ListConfigVersions(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.ConfigVersion, *fleet.PaginationMetadata, error) {
 	s.mu.Lock()
-	s.TeamsSummaryFuncInvoked = true
+	s.ListConfigVersionsFuncInvoked = true
 	s.mu.Unlock()
-	return s.TeamsSummaryFunc(ctx)
+	return s.ListConfigVersionsFunc(ctx, teamID, opt)
 }
 
-func (s *DataStore) SearchTeams(ctx context.Context, filter fleet.TeamFilter, matchQuery string, omit ...uint) ([]*fleet.Team, error) {
+func (s *DataStore) ListEmailTemplates(ctx context.Context) ([]*fleet.EmailTemplate, error) {
 	s.mu.Lock()
-	s.SearchTeamsFuncInvoked = true
+	s.ListEmailTemplatesFuncInvoked = true
 	s.mu.Unlock()
-	return s.SearchTeamsFunc(ctx, filter, matchQuery, omit...)
+	return s.ListEmailTemplatesFunc(ctx)
 }
 
-func (s *DataStore) TeamEnrollSecrets(ctx context.Context, teamID uint) ([]*fleet.EnrollSecret, error) {
+func (s *DataStore) EmailTemplate(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
 	s.mu.Lock()
-	s.TeamEnrollSecretsFuncInvoked = true
+	s.EmailTemplateFuncInvoked = true
 	s.mu.Unlock()
-	return s.TeamEnrollSecretsFunc(ctx, teamID)
+	return s.EmailTemplateFunc(ctx, name)
 }
 
-func (s *DataStore) DeleteIntegrationsFromTeams(ctx context.Context, deletedIntgs fleet.Integrations) error {
+func (s *DataStore) SaveEmailTemplate(ctx context.Context, tmpl *fleet.EmailTemplate) (*fleet.EmailTemplate, error) {
 	s.mu.Lock()
-	s.DeleteIntegrationsFromTeamsFuncInvoked = true
+	s.SaveEmailTemplateFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteIntegrationsFromTeamsFunc(ctx, deletedIntgs)
+	return s.SaveEmailTemplateFunc(ctx, tmpl)
 }
 
-func (s *DataStore) TeamExists(ctx context.Context, teamID uint) (bool, error) {
+func (s *DataStore) DeleteEmailTemplate(ctx context.Context, name fleet.EmailTemplateName) error {
 	s.mu.Lock()
-	s.TeamExistsFuncInvoked = true
+	s.DeleteEmailTemplateFuncInvoked = true
 	s.mu.Unlock()
-	return s.TeamExistsFunc(ctx, teamID)
+	return s.DeleteEmailTemplateFunc(ctx, name)
 }
 
-func (s *DataStore) ListSoftwareTitles(ctx context.Context, opt fleet.SoftwareTitleListOptions, tmFilter fleet.TeamFilter) ([]fleet.SoftwareTitle, int, *fleet.PaginationMetadata, error) {
+func (s *DataStore) ListTranslations(ctx context.Context) ([]*fleet.Translations, error) {
 	s.mu.Lock()
-	s.ListSoftwareTitlesFuncInvoked = true
+	s.ListTranslationsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListSoftwareTitlesFunc(ctx, opt, tmFilter)
+	return s.ListTranslationsFunc(ctx)
 }
 
-func (s *DataStore) SoftwareTitleByID(ctx context.Context, id uint, teamID *uint, tmFilter fleet.TeamFilter) (*fleet.SoftwareTitle, error) {
+func (s *DataStore) Translations(ctx context.Context, locale string) (*fleet.Translations, error) {
 	s.mu.Lock()
-	s.SoftwareTitleByIDFuncInvoked = true
+	s.TranslationsFuncInvoked = true
 	s.mu.Unlock()
-	return s.SoftwareTitleByIDFunc(ctx, id, teamID, tmFilter)
+	return s.TranslationsFunc(ctx, locale)
 }
 
-func (s *DataStore) ListSoftwareForVulnDetection(ctx context.Context, hostID uint) ([]fleet.Software, error) {
+func (s *DataStore) ApplyTranslations(ctx context.Context, t *fleet.Translations) (*fleet.Translations, error) {
 	s.mu.Lock()
-	s.ListSoftwareForVulnDetectionFuncInvoked = true
+	s.ApplyTranslationsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListSoftwareForVulnDetectionFunc(ctx, hostID)
+	return s.ApplyTranslationsFunc(ctx, t)
 }
 
-func (s *DataStore) ListSoftwareVulnerabilitiesByHostIDsSource(ctx context.Context, hostIDs []uint, source fleet.VulnerabilitySource) (map[uint][]fleet.SoftwareVulnerability, error) {
+func (s *DataStore) DeleteTranslations(ctx context.Context, locale string) error {
 	s.mu.Lock()
-	s.ListSoftwareVulnerabilitiesByHostIDsSourceFuncInvoked = true
+	s.DeleteTranslationsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListSoftwareVulnerabilitiesByHostIDsSourceFunc(ctx, hostIDs, source)
+	return s.DeleteTranslationsFunc(ctx, locale)
 }
 
-func (s *DataStore) LoadHostSoftware(ctx context.Context, host *fleet.Host, includeCVEScores bool) error {
+func (s *DataStore) NewHostGroup(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
 	s.mu.Lock()
-	s.LoadHostSoftwareFuncInvoked = true
+	s.NewHostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.LoadHostSoftwareFunc(ctx, host, includeCVEScores)
+	return s.NewHostGroupFunc(ctx, group)
 }
 
-func (s *DataStore) AllSoftwareIterator(ctx context.Context, query fleet.SoftwareIterQueryOptions) (fleet.SoftwareIterator, error) {
+func (s *DataStore) HostGroup(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
 	s.mu.Lock()
-	s.AllSoftwareIteratorFuncInvoked = true
+	s.HostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.AllSoftwareIteratorFunc(ctx, query)
+	return s.HostGroupFunc(ctx, filter, id)
 }
 
-func (s *DataStore) UpsertSoftwareCPEs(ctx context.Context, cpes []fleet.SoftwareCPE) (int64, error) {
+func (s *DataStore) ListHostGroups(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.HostGroup, error) {
 	s.mu.Lock()
-	s.UpsertSoftwareCPEsFuncInvoked = true
+	s.ListHostGroupsFuncInvoked = true
 	s.mu.Unlock()
-	return s.UpsertSoftwareCPEsFunc(ctx, cpes)
+	return s.ListHostGroupsFunc(ctx, filter, opt)
 }
 
-func (s *DataStore) DeleteSoftwareCPEs(ctx context.Context, cpes []fleet.SoftwareCPE) (int64, error) {
+func (s *DataStore) SaveHostGroup(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
 	s.mu.Lock()
-	s.DeleteSoftwareCPEsFuncInvoked = true
+	s.SaveHostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteSoftwareCPEsFunc(ctx, cpes)
+	return s.SaveHostGroupFunc(ctx, group)
 }
 
-func (s *DataStore) ListSoftwareCPEs(ctx context.Context) ([]fleet.SoftwareCPE, error) {
+func (s *DataStore) DeleteHostGroup(ctx context.Context, id uint) error {
 	s.mu.Lock()
-	s.ListSoftwareCPEsFuncInvoked = true
+	s.DeleteHostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListSoftwareCPEsFunc(ctx)
+	return s.DeleteHostGroupFunc(ctx, id)
 }
 
-func (s *DataStore) InsertSoftwareVulnerability(ctx context.Context, vuln fleet.SoftwareVulnerability, source fleet.VulnerabilitySource) (bool, error) {
+func (s *DataStore) AddHostsToHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error {
 	s.mu.Lock()
-	s.InsertSoftwareVulnerabilityFuncInvoked = true
+	s.AddHostsToHostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.InsertSoftwareVulnerabilityFunc(ctx, vuln, source)
+	return s.AddHostsToHostGroupFunc(ctx, groupID, hostIDs)
 }
 
-func (s *DataStore) SoftwareByID(ctx context.Context, id uint, teamID *uint, includeCVEScores bool, tmFilter *fleet.TeamFilter) (*fleet.Software, error) {
+func (s *DataStore) RemoveHostsFromHostGroup(ctx context.Context, groupID uint, hostIDs []uint) error {
 	s.mu.Lock()
-	s.SoftwareByIDFuncInvoked = true
+	s.RemoveHostsFromHostGroupFuncInvoked = true
 	s.mu.Unlock()
-	return s.SoftwareByIDFunc(ctx, id, teamID, includeCVEScores, tmFilter)
+	return s.RemoveHostsFromHostGroupFunc(ctx, groupID, hostIDs)
 }
 
-func (s *DataStore) ListSoftwareByHostIDShort(ctx context.Context, hostID uint) ([]fleet.Software, error) {
+func (s *DataStore) GetHostCustomFields(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
 	s.mu.Lock()
-	s.ListSoftwareByHostIDShortFuncInvoked = true
+	s.GetHostCustomFieldsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListSoftwareByHostIDShortFunc(ctx, hostID)
+	return s.GetHostCustomFieldsFunc(ctx, hostID)
 }
 
-func (s *DataStore) SyncHostsSoftware(ctx context.Context, updatedAt time.Time) error {
+func (s *DataStore) UpdateHostCustomFields(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
 	s.mu.Lock()
-	s.SyncHostsSoftwareFuncInvoked = true
+	s.UpdateHostCustomFieldsFuncInvoked = true
 	s.mu.Unlock()
-	return s.SyncHostsSoftwareFunc(ctx, updatedAt)
+	return s.UpdateHostCustomFieldsFunc(ctx, hostID, payload)
 }
 
-func (s *DataStore) ReconcileSoftwareTitles(ctx context.Context) error {
+func (s *DataStore) DeleteHostCustomFieldValuesNotIn(ctx context.Context, names []string) error {
 	s.mu.Lock()
-	s.ReconcileSoftwareTitlesFuncInvoked = true
+	s.DeleteHostCustomFieldValuesNotInFuncInvoked = true
 	s.mu.Unlock()
-	return s.ReconcileSoftwareTitlesFunc(ctx)
+	return s.DeleteHostCustomFieldValuesNotInFunc(ctx, names)
 }
 
-func (s *DataStore) SyncHostsSoftwareTitles(ctx context.Context, updatedAt time.Time) error {
+func (s *DataStore) ListHostsForWarrantyLookup(ctx context.Context, vendors []fleet.WarrantyVendor, checkedBefore time.Time, limit int) ([]*fleet.Host, error) {
 	s.mu.Lock()
-	s.SyncHostsSoftwareTitlesFuncInvoked = true
+	s.ListHostsForWarrantyLookupFuncInvoked = true
 	s.mu.Unlock()
-	return s.SyncHostsSoftwareTitlesFunc(ctx, updatedAt)
+	return s.ListHostsForWarrantyLookupFunc(ctx, vendors, checkedBefore, limit)
 }
 
-func (s *DataStore) HostVulnSummariesBySoftwareIDs(ctx context.Context, softwareIDs []uint) ([]fleet.HostVulnerabilitySummary, error) {
+func (s *DataStore) UpsertHostWarranties(ctx context.Context, warranties []*fleet.HostWarranty) error {
 	s.mu.Lock()
-	s.HostVulnSummariesBySoftwareIDsFuncInvoked = true
+	s.UpsertHostWarrantiesFuncInvoked = true
 	s.mu.Unlock()
-	return s.HostVulnSummariesBySoftwareIDsFunc(ctx, softwareIDs)
+	return s.UpsertHostWarrantiesFunc(ctx, warranties)
 }
 
-func (s *DataStore) HostsByCVE(ctx context.Context, cve string) ([]fleet.HostVulnerabilitySummary, error) {
+func (s *DataStore) ListHostHealthScoreInputs(ctx context.Context, afterHostID uint, recoveredSince time.Time, limit int) ([]*fleet.HostHealthScoreInputs, error) {
 	s.mu.Lock()
-	s.HostsByCVEFuncInvoked = true
+	s.ListHostHealthScoreInputsFuncInvoked = true
 	s.mu.Unlock()
-	return s.HostsByCVEFunc(ctx, cve)
+	return s.ListHostHealthScoreInputsFunc(ctx, afterHostID, recoveredSince, limit)
 }
 
-func (s *DataStore) InsertCVEMeta(ctx context.Context, cveMeta []fleet.CVEMeta) error {
+func (s *DataStore) UpsertHostHealthScores(ctx context.Context, scores []*fleet.HostHealthScore) error {
 	s.mu.Lock()
-	s.InsertCVEMetaFuncInvoked = true
+	s.UpsertHostHealthScoresFuncInvoked = true
 	s.mu.Unlock()
-	return s.InsertCVEMetaFunc(ctx, cveMeta)
+	return s.UpsertHostHealthScoresFunc(ctx, scores)
 }
 
-func (s *DataStore) ListCVEs(ctx context.Context, maxAge time.Duration) ([]fleet.CVEMeta, error) {
+func (s *DataStore) GetHostHealthScores(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
 	s.mu.Lock()
-	s.ListCVEsFuncInvoked = true
+	s.GetHostHealthScoresFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListCVEsFunc(ctx, maxAge)
+	return s.GetHostHealthScoresFunc(ctx, hostIDs)
 }
 
-func (s *DataStore) GetHostOperatingSystem(ctx context.Context, hostID uint) (*fleet.OperatingSystem, error) {
+func (s *DataStore) ListHostsPendingApproval(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostPendingApproval, error) {
 	s.mu.Lock()
-	s.GetHostOperatingSystemFuncInvoked = true
+	s.ListHostsPendingApprovalFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetHostOperatingSystemFunc(ctx, hostID)
+	return s.ListHostsPendingApprovalFunc(ctx, filter)
 }
 
-func (s *DataStore) ListOperatingSystems(ctx context.Context) ([]fleet.OperatingSystem, error) {
+func (s *DataStore) ApproveHostEnrollment(ctx context.Context, hostID uint) error {
 	s.mu.Lock()
-	s.ListOperatingSystemsFuncInvoked = true
+	s.ApproveHostEnrollmentFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListOperatingSystemsFunc(ctx)
+	return s.ApproveHostEnrollmentFunc(ctx, hostID)
 }
 
-func (s *DataStore) ListOperatingSystemsForPlatform(ctx context.Context, platform string) ([]fleet.OperatingSystem, error) {
+func (s *DataStore) ListDuplicateHosts(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHostSet, error) {
 	s.mu.Lock()
-	s.ListOperatingSystemsForPlatformFuncInvoked = true
+	s.ListDuplicateHostsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListOperatingSystemsForPlatformFunc(ctx, platform)
+	return s.ListDuplicateHostsFunc(ctx, filter)
 }
 
-func (s *DataStore) UpdateHostOperatingSystem(ctx context.Context, hostID uint, hostOS fleet.OperatingSystem) error {
+func (s *DataStore) MergeHosts(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
 	s.mu.Lock()
-	s.UpdateHostOperatingSystemFuncInvoked = true
+	s.MergeHostsFuncInvoked = true
 	s.mu.Unlock()
-	return s.UpdateHostOperatingSystemFunc(ctx, hostID, hostOS)
+	return s.MergeHostsFunc(ctx, targetHostID, duplicateHostIDs)
 }
 
-func (s *DataStore) CleanupHostOperatingSystems(ctx context.Context) error {
+func (s *DataStore) NewTenant(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
 	s.mu.Lock()
-	s.CleanupHostOperatingSystemsFuncInvoked = true
+	s.NewTenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.CleanupHostOperatingSystemsFunc(ctx)
+	return s.NewTenantFunc(ctx, tenant)
 }
 
-func (s *DataStore) MDMTurnOff(ctx context.Context, uuid string) error {
+func (s *DataStore) Tenant(ctx context.Context, id uint) (*fleet.Tenant, error) {
 	s.mu.Lock()
-	s.MDMTurnOffFuncInvoked = true
+	s.TenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.MDMTurnOffFunc(ctx, uuid)
+	return s.TenantFunc(ctx, id)
 }
 
-func (s *DataStore) NewActivity(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+func (s *DataStore) ListTenants(ctx context.Context) ([]*fleet.Tenant, error) {
 	s.mu.Lock()
-	s.NewActivityFuncInvoked = true
+	s.ListTenantsFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewActivityFunc(ctx, user, activity)
+	return s.ListTenantsFunc(ctx)
 }
 
-func (s *DataStore) ListActivities(ctx context.Context, opt fleet.ListActivitiesOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+func (s *DataStore) SaveTenant(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
 	s.mu.Lock()
-	s.ListActivitiesFuncInvoked = true
+	s.SaveTenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListActivitiesFunc(ctx, opt)
+	return s.SaveTenantFunc(ctx, tenant)
 }
 
-func (s *DataStore) MarkActivitiesAsStreamed(ctx context.Context, activityIDs []uint) error {
+func (s *DataStore) DeleteTenant(ctx context.Context, id uint) error {
 	s.mu.Lock()
-	s.MarkActivitiesAsStreamedFuncInvoked = true
+	s.DeleteTenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.MarkActivitiesAsStreamedFunc(ctx, activityIDs)
+	return s.DeleteTenantFunc(ctx, id)
 }
 
-func (s *DataStore) ListHostUpcomingActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+func (s *DataStore) SetTeamsTenant(ctx context.Context, tenantID *uint, teamIDs []uint) error {
 	s.mu.Lock()
-	s.ListHostUpcomingActivitiesFuncInvoked = true
+	s.SetTeamsTenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListHostUpcomingActivitiesFunc(ctx, hostID, opt)
+	return s.SetTeamsTenantFunc(ctx, tenantID, teamIDs)
 }
 
-func (s *DataStore) ListHostPastActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+func (s *DataStore) SetUsersTenant(ctx context.Context, tenantID *uint, userIDs []uint) error {
 	s.mu.Lock()
-	s.ListHostPastActivitiesFuncInvoked = true
+	s.SetUsersTenantFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListHostPastActivitiesFunc(ctx, hostID, opt)
+	return s.SetUsersTenantFunc(ctx, tenantID, userIDs)
 }
 
-func (s *DataStore) IsExecutionPendingForHost(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error) {
+func (s *DataStore) TenantAppConfig(ctx context.Context, tenantID uint) (*fleet.AppConfig, error) {
 	s.mu.Lock()
-	s.IsExecutionPendingForHostFuncInvoked = true
+	s.TenantAppConfigFuncInvoked = true
 	s.mu.Unlock()
-	return s.IsExecutionPendingForHostFunc(ctx, hostID, scriptID)
+	return s.TenantAppConfigFunc(ctx, tenantID)
 }
 
-func (s *DataStore) ShouldSendStatistics(ctx context.Context, frequency time.Duration, config config.FleetConfig) (fleet.StatisticsPayload, bool, error) {
+func (s *DataStore) SetTenantMDMAssets(ctx context.Context, tenantID uint, assets []fleet.TenantMDMAsset) error {
 	s.mu.Lock()
-	s.ShouldSendStatisticsFuncInvoked = true
+	s.SetTenantMDMAssetsFuncInvoked = true
 	s.mu.Unlock()
-	return s.ShouldSendStatisticsFunc(ctx, frequency, config)
+	return s.SetTenantMDMAssetsFunc(ctx, tenantID, assets)
 }
 
-func (s *DataStore) RecordStatisticsSent(ctx context.Context) error {
+func (s *DataStore) GetTenantMDMAssets(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error) {
 	s.mu.Lock()
-	s.RecordStatisticsSentFuncInvoked = true
+	s.GetTenantMDMAssetsFuncInvoked = true
 	s.mu.Unlock()
-	return s.RecordStatisticsSentFunc(ctx)
+	return s.GetTenantMDMAssetsFunc(ctx, tenantID, names)
 }
 
-func (s *DataStore) CleanupStatistics(ctx context.Context) error {
+func (s *DataStore) RotateTenantEncryptionKey(ctx context.Context, tenantID uint) error {
 	s.mu.Lock()
-	s.CleanupStatisticsFuncInvoked = true
+	s.RotateTenantEncryptionKeyFuncInvoked = true
 	s.mu.Unlock()
-	return s.CleanupStatisticsFunc(ctx)
+	return s.RotateTenantEncryptionKeyFunc(ctx, tenantID)
 }
 
-func (s *DataStore) ApplyPolicySpecs(ctx context.Context, authorID uint, specs []*fleet.PolicySpec) error {
+func (s *DataStore) NewDataExport(ctx context.Context, export *fleet.DataExport) (*fleet.DataExport, error) {
 	s.mu.Lock()
-	s.ApplyPolicySpecsFuncInvoked = true
+	s.NewDataExportFuncInvoked = true
 	s.mu.Unlock()
-	return s.ApplyPolicySpecsFunc(ctx, authorID, specs)
+	return s.NewDataExportFunc(ctx, export)
 }
 
-func (s *DataStore) NewGlobalPolicy(ctx context.Context, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
+func (s *DataStore) DataExport(ctx context.Context, id uint) (*fleet.DataExport, error) {
 	s.mu.Lock()
-	s.NewGlobalPolicyFuncInvoked = true
+	s.DataExportFuncInvoked = true
 	s.mu.Unlock()
-	return s.NewGlobalPolicyFunc(ctx, authorID, args)
+	return s.DataExportFunc(ctx, id)
 }
 
-func (s *DataStore) Policy(ctx context.Context, id uint) (*fleet.Policy, error) {
+func (s *DataStore) DataExportContent(ctx context.Context, id uint) ([]byte, error) {
 	s.mu.Lock()
-	s.PolicyFuncInvoked = true
+	s.DataExportContentFuncInvoked = true
 	s.mu.Unlock()
-	return s.PolicyFunc(ctx, id)
+	return s.DataExportContentFunc(ctx, id)
 }
 
-func (s *DataStore) SavePolicy(ctx context.Context, p *fleet.Policy, shouldRemoveAllPolicyMemberships bool, removePolicyStats bool) error {
+func (s *DataStore) ListPendingDataExports(ctx context.Context) ([]*fleet.DataExport, error) {
 	s.mu.Lock()
-	s.SavePolicyFuncInvoked = true
+	s.ListPendingDataExportsFuncInvoked = true
 	s.mu.Unlock()
-	return s.SavePolicyFunc(ctx, p, shouldRemoveAllPolicyMemberships, removePolicyStats)
+	return s.ListPendingDataExportsFunc(ctx)
 }
 
-func (s *DataStore) ListGlobalPolicies(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) {
+func (s *DataStore) CompleteDataExport(ctx context.Context, id uint, data []byte, exportErr string) error {
 	s.mu.Lock()
-	s.ListGlobalPoliciesFuncInvoked = true
+	s.CompleteDataExportFuncInvoked = true
 	s.mu.Unlock()
-	return s.ListGlobalPoliciesFunc(ctx, opts)
+	return s.CompleteDataExportFunc(ctx, id, data, exportErr)
 }
 
-func (s *DataStore) PoliciesByID(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
+func (s *DataStore) CleanupDataExports(ctx context.Context, createdBefore time.Time) (int64, error) {
 	s.mu.Lock()
-	s.PoliciesByIDFuncInvoked = true
+	s.CleanupDataExportsFuncInvoked = true
 	s.mu.Unlock()
-	return s.PoliciesByIDFunc(ctx, ids)
+	return s.CleanupDataExportsFunc(ctx, createdBefore)
 }
 
-func (s *DataStore) DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error) {
+func (s *DataStore) NewAppConfig(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error) {
 	s.mu.Lock()
-	s.DeleteGlobalPoliciesFuncInvoked = true
+	s.NewAppConfigFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteGlobalPoliciesFunc(ctx, ids)
+	return s.NewAppConfigFunc(ctx, info)
 }
 
-func (s *DataStore) CountPolicies(ctx context.Context, teamID *uint, matchQuery string) (int, error) {
+func (s *DataStore) AppConfig(ctx context.Context) (*fleet.AppConfig, error) {
 	s.mu.Lock()
-	s.CountPoliciesFuncInvoked = true
+	s.AppConfigFuncInvoked = true
 	s.mu.Unlock()
-	return s.CountPoliciesFunc(ctx, teamID, matchQuery)
+	return s.AppConfigFunc(ctx)
+}
+
+func (s *DataStore) SaveAppConfig(ctx context.Context, info *fleet.AppConfig) error {
+	s.mu.Lock()
+	s.SaveAppConfigFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveAppConfigFunc(ctx, info)
+}
+
+func (s *DataStore) GetEnrollSecrets(ctx context.Context, teamID *uint) ([]*fleet.EnrollSecret, error) {
+	s.mu.Lock()
+	s.GetEnrollSecretsFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetEnrollSecretsFunc(ctx, teamID)
+}
+
+func (s *DataStore) ApplyEnrollSecrets(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
+	s.mu.Lock()
+	s.ApplyEnrollSecretsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyEnrollSecretsFunc(ctx, teamID, secrets)
+}
+
+func (s *DataStore) AggregateEnrollSecretPerTeam(ctx context.Context) ([]*fleet.EnrollSecret, error) {
+	s.mu.Lock()
+	s.AggregateEnrollSecretPerTeamFuncInvoked = true
+	s.mu.Unlock()
+	return s.AggregateEnrollSecretPerTeamFunc(ctx)
+}
+
+func (s *DataStore) NewInvite(ctx context.Context, i *fleet.Invite) (*fleet.Invite, error) {
+	s.mu.Lock()
+	s.NewInviteFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewInviteFunc(ctx, i)
+}
+
+func (s *DataStore) ListInvites(ctx context.Context, opt fleet.ListOptions) ([]*fleet.Invite, error) {
+	s.mu.Lock()
+	s.ListInvitesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListInvitesFunc(ctx, opt)
+}
+
+func (s *DataStore) Invite(ctx context.Context, id uint) (*fleet.Invite, error) {
+	s.mu.Lock()
+	s.InviteFuncInvoked = true
+	s.mu.Unlock()
+	return s.InviteFunc(ctx, id)
+}
+
+func (s *DataStore) InviteByEmail(ctx context.Context, email string) (*fleet.Invite, error) {
+	s.mu.Lock()
+	s.InviteByEmailFuncInvoked = true
+	s.mu.Unlock()
+	return s.InviteByEmailFunc(ctx, email)
+}
+
+func (s *DataStore) InviteByToken(ctx context.Context, token string) (*fleet.Invite, error) {
+	s.mu.Lock()
+	s.InviteByTokenFuncInvoked = true
+	s.mu.Unlock()
+	return s.InviteByTokenFunc(ctx, token)
+}
+
+func (s *DataStore) DeleteInvite(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteInviteFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteInviteFunc(ctx, id)
+}
+
+func (s *DataStore) UpdateInvite(ctx context.Context, id uint, i *fleet.Invite) (*fleet.Invite, error) {
+	s.mu.Lock()
+	s.UpdateInviteFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateInviteFunc(ctx, id, i)
+}
+
+func (s *DataStore) ListScheduledQueriesInPackWithStats(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.ScheduledQuery, error) {
+	s.mu.Lock()
+	s.ListScheduledQueriesInPackWithStatsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListScheduledQueriesInPackWithStatsFunc(ctx, id, opts)
+}
+
+func (s *DataStore) NewScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
+	s.mu.Lock()
+	s.NewScheduledQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewScheduledQueryFunc(ctx, sq, opts...)
+}
+
+func (s *DataStore) SaveScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+	s.mu.Lock()
+	s.SaveScheduledQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveScheduledQueryFunc(ctx, sq)
+}
+
+func (s *DataStore) DeleteScheduledQuery(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteScheduledQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteScheduledQueryFunc(ctx, id)
+}
+
+func (s *DataStore) ScheduledQuery(ctx context.Context, id uint) (*fleet.ScheduledQuery, error) {
+	s.mu.Lock()
+	s.ScheduledQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.ScheduledQueryFunc(ctx, id)
+}
+
+func (s *DataStore) CleanupExpiredHosts(ctx context.Context) ([]uint, error) {
+	s.mu.Lock()
+	s.CleanupExpiredHostsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupExpiredHostsFunc(ctx)
+}
+
+func (s *DataStore) ScheduledQueryIDsByName(ctx context.Context, batchSize int, packAndSchedQueryNames ...[2]string) ([]uint, error) {
+	s.mu.Lock()
+	s.ScheduledQueryIDsByNameFuncInvoked = true
+	s.mu.Unlock()
+	return s.ScheduledQueryIDsByNameFunc(ctx, batchSize, packAndSchedQueryNames...)
+}
+
+func (s *DataStore) QueryResultRows(ctx context.Context, queryID uint, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+	s.mu.Lock()
+	s.QueryResultRowsFuncInvoked = true
+	s.mu.Unlock()
+	return s.QueryResultRowsFunc(ctx, queryID, filter)
+}
+
+func (s *DataStore) QueryResultRowsForHost(ctx context.Context, queryID uint, hostID uint) ([]*fleet.ScheduledQueryResultRow, error) {
+	s.mu.Lock()
+	s.QueryResultRowsForHostFuncInvoked = true
+	s.mu.Unlock()
+	return s.QueryResultRowsForHostFunc(ctx, queryID, hostID)
+}
+
+func (s *DataStore) ResultCountForQuery(ctx context.Context, queryID uint) (int, error) {
+	s.mu.Lock()
+	s.ResultCountForQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.ResultCountForQueryFunc(ctx, queryID)
+}
+
+func (s *DataStore) ResultCountForQueryAndHost(ctx context.Context, queryID uint, hostID uint) (int, error) {
+	s.mu.Lock()
+	s.ResultCountForQueryAndHostFuncInvoked = true
+	s.mu.Unlock()
+	return s.ResultCountForQueryAndHostFunc(ctx, queryID, hostID)
+}
+
+func (s *DataStore) OverwriteQueryResultRows(ctx context.Context, rows []*fleet.ScheduledQueryResultRow) error {
+	s.mu.Lock()
+	s.OverwriteQueryResultRowsFuncInvoked = true
+	s.mu.Unlock()
+	return s.OverwriteQueryResultRowsFunc(ctx, rows)
+}
+
+func (s *DataStore) CleanupDiscardedQueryResults(ctx context.Context) error {
+	s.mu.Lock()
+	s.CleanupDiscardedQueryResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupDiscardedQueryResultsFunc(ctx)
+}
+
+func (s *DataStore) NewTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+	s.mu.Lock()
+	s.NewTeamFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewTeamFunc(ctx, team)
+}
+
+func (s *DataStore) SaveTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+	s.mu.Lock()
+	s.SaveTeamFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveTeamFunc(ctx, team)
+}
+
+func (s *DataStore) Team(ctx context.Context, tid uint) (*fleet.Team, error) {
+	s.mu.Lock()
+	s.TeamFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamFunc(ctx, tid)
+}
+
+func (s *DataStore) DeleteTeam(ctx context.Context, tid uint) error {
+	s.mu.Lock()
+	s.DeleteTeamFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteTeamFunc(ctx, tid)
+}
+
+func (s *DataStore) TeamByName(ctx context.Context, name string) (*fleet.Team, error) {
+	s.mu.Lock()
+	s.TeamByNameFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamByNameFunc(ctx, name)
+}
+
+func (s *DataStore) ListTeams(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Team, error) {
+	s.mu.Lock()
+	s.ListTeamsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListTeamsFunc(ctx, filter, opt)
+}
+
+func (s *DataStore) TeamsSummary(ctx context.Context) ([]*fleet.TeamSummary, error) {
+	s.mu.Lock()
+	s.TeamsSummaryFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamsSummaryFunc(ctx)
+}
+
+func (s *DataStore) SearchTeams(ctx context.Context, filter fleet.TeamFilter, matchQuery string, omit ...uint) ([]*fleet.Team, error) {
+	s.mu.Lock()
+	s.SearchTeamsFuncInvoked = true
+	s.mu.Unlock()
+	return s.SearchTeamsFunc(ctx, filter, matchQuery, omit...)
+}
+
+func (s *DataStore) TeamEnrollSecrets(ctx context.Context, teamID uint) ([]*fleet.EnrollSecret, error) {
+	s.mu.Lock()
+	s.TeamEnrollSecretsFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamEnrollSecretsFunc(ctx, teamID)
+}
+
+func (s *DataStore) DeleteIntegrationsFromTeams(ctx context.Context, deletedIntgs fleet.Integrations) error {
+	s.mu.Lock()
+	s.DeleteIntegrationsFromTeamsFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteIntegrationsFromTeamsFunc(ctx, deletedIntgs)
+}
+
+func (s *DataStore) TeamExists(ctx context.Context, teamID uint) (bool, error) {
+	s.mu.Lock()
+	s.TeamExistsFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamExistsFunc(ctx, teamID)
+}
+
+func (s *DataStore) ListSoftwareTitles(ctx context.Context, opt fleet.SoftwareTitleListOptions, tmFilter fleet.TeamFilter) ([]fleet.SoftwareTitle, int, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListSoftwareTitlesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListSoftwareTitlesFunc(ctx, opt, tmFilter)
+}
+
+func (s *DataStore) SoftwareTitleByID(ctx context.Context, id uint, teamID *uint, tmFilter fleet.TeamFilter) (*fleet.SoftwareTitle, error) {
+	s.mu.Lock()
+	s.SoftwareTitleByIDFuncInvoked = true
+	s.mu.Unlock()
+	return s.SoftwareTitleByIDFunc(ctx, id, teamID, tmFilter)
+}
+
+func (s *DataStore) ListSoftwareForVulnDetection(ctx context.Context, hostID uint) ([]fleet.Software, error) {
+	s.mu.Lock()
+	s.ListSoftwareForVulnDetectionFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListSoftwareForVulnDetectionFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListSoftwareVulnerabilitiesByHostIDsSource(ctx context.Context, hostIDs []uint, source fleet.VulnerabilitySource) (map[uint][]fleet.SoftwareVulnerability, error) {
+	s.mu.Lock()
+	s.ListSoftwareVulnerabilitiesByHostIDsSourceFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListSoftwareVulnerabilitiesByHostIDsSourceFunc(ctx, hostIDs, source)
+}
+
+func (s *DataStore) LoadHostSoftware(ctx context.Context, host *fleet.Host, includeCVEScores bool) error {
+	s.mu.Lock()
+	s.LoadHostSoftwareFuncInvoked = true
+	s.mu.Unlock()
+	return s.LoadHostSoftwareFunc(ctx, host, includeCVEScores)
+}
+
+func (s *DataStore) AllSoftwareIterator(ctx context.Context, query fleet.SoftwareIterQueryOptions) (fleet.SoftwareIterator, error) {
+	s.mu.Lock()
+	s.AllSoftwareIteratorFuncInvoked = true
+	s.mu.Unlock()
+	return s.AllSoftwareIteratorFunc(ctx, query)
+}
+
+func (s *DataStore) UpsertSoftwareCPEs(ctx context.Context, cpes []fleet.SoftwareCPE) (int64, error) {
+	s.mu.Lock()
+	s.UpsertSoftwareCPEsFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpsertSoftwareCPEsFunc(ctx, cpes)
+}
+
+func (s *DataStore) DeleteSoftwareCPEs(ctx context.Context, cpes []fleet.SoftwareCPE) (int64, error) {
+	s.mu.Lock()
+	s.DeleteSoftwareCPEsFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteSoftwareCPEsFunc(ctx, cpes)
+}
+
+func (s *DataStore) ListSoftwareCPEs(ctx context.Context) ([]fleet.SoftwareCPE, error) {
+	s.mu.Lock()
+	s.ListSoftwareCPEsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListSoftwareCPEsFunc(ctx)
+}
+
+func (s *DataStore) InsertSoftwareVulnerability(ctx context.Context, vuln fleet.SoftwareVulnerability, source fleet.VulnerabilitySource) (bool, error) {
+	s.mu.Lock()
+	s.InsertSoftwareVulnerabilityFuncInvoked = true
+	s.mu.Unlock()
+	return s.InsertSoftwareVulnerabilityFunc(ctx, vuln, source)
+}
+
+func (s *DataStore) SoftwareByID(ctx context.Context, id uint, teamID *uint, includeCVEScores bool, tmFilter *fleet.TeamFilter) (*fleet.Software, error) {
+	s.mu.Lock()
+	s.SoftwareByIDFuncInvoked = true
+	s.mu.Unlock()
+	return s.SoftwareByIDFunc(ctx, id, teamID, includeCVEScores, tmFilter)
+}
+
+func (s *DataStore) ListSoftwareByHostIDShort(ctx context.Context, hostID uint) ([]fleet.Software, error) {
+	s.mu.Lock()
+	s.ListSoftwareByHostIDShortFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListSoftwareByHostIDShortFunc(ctx, hostID)
+}
+
+func (s *DataStore) SyncHostsSoftware(ctx context.Context, updatedAt time.Time) error {
+	s.mu.Lock()
+	s.SyncHostsSoftwareFuncInvoked = true
+	s.mu.Unlock()
+	return s.SyncHostsSoftwareFunc(ctx, updatedAt)
+}
+
+func (s *DataStore) ReconcileSoftwareTitles(ctx context.Context) error {
+	s.mu.Lock()
+	s.ReconcileSoftwareTitlesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReconcileSoftwareTitlesFunc(ctx)
+}
+
+func (s *DataStore) SyncHostsSoftwareTitles(ctx context.Context, updatedAt time.Time) error {
+	s.mu.Lock()
+	s.SyncHostsSoftwareTitlesFuncInvoked = true
+	s.mu.Unlock()
+	return s.SyncHostsSoftwareTitlesFunc(ctx, updatedAt)
+}
+
+func (s *DataStore) HostVulnSummariesBySoftwareIDs(ctx context.Context, softwareIDs []uint) ([]fleet.HostVulnerabilitySummary, error) {
+	s.mu.Lock()
+	s.HostVulnSummariesBySoftwareIDsFuncInvoked = true
+	s.mu.Unlock()
+	return s.HostVulnSummariesBySoftwareIDsFunc(ctx, softwareIDs)
+}
+
+func (s *DataStore) HostsByCVE(ctx context.Context, cve string) ([]fleet.HostVulnerabilitySummary, error) {
+	s.mu.Lock()
+	s.HostsByCVEFuncInvoked = true
+	s.mu.Unlock()
+	return s.HostsByCVEFunc(ctx, cve)
+}
+
+func (s *DataStore) InsertCVEMeta(ctx context.Context, cveMeta []fleet.CVEMeta) error {
+	s.mu.Lock()
+	s.InsertCVEMetaFuncInvoked = true
+	s.mu.Unlock()
+	return s.InsertCVEMetaFunc(ctx, cveMeta)
+}
+
+func (s *DataStore) ListCVEs(ctx context.Context, maxAge time.Duration) ([]fleet.CVEMeta, error) {
+	s.mu.Lock()
+	s.ListCVEsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCVEsFunc(ctx, maxAge)
+}
+
+func (s *DataStore) GetHostOperatingSystem(ctx context.Context, hostID uint) (*fleet.OperatingSystem, error) {
+	s.mu.Lock()
+	s.GetHostOperatingSystemFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostOperatingSystemFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListOperatingSystems(ctx context.Context) ([]fleet.OperatingSystem, error) {
+	s.mu.Lock()
+	s.ListOperatingSystemsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListOperatingSystemsFunc(ctx)
+}
+
+func (s *DataStore) ListOperatingSystemsForPlatform(ctx context.Context, platform string) ([]fleet.OperatingSystem, error) {
+	s.mu.Lock()
+	s.ListOperatingSystemsForPlatformFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListOperatingSystemsForPlatformFunc(ctx, platform)
+}
+
+func (s *DataStore) UpdateHostOperatingSystem(ctx context.Context, hostID uint, hostOS fleet.OperatingSystem) error {
+	s.mu.Lock()
+	s.UpdateHostOperatingSystemFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateHostOperatingSystemFunc(ctx, hostID, hostOS)
+}
+
+func (s *DataStore) CleanupHostOperatingSystems(ctx context.Context) error {
+	s.mu.Lock()
+	s.CleanupHostOperatingSystemsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupHostOperatingSystemsFunc(ctx)
+}
+
+func (s *DataStore) MDMTurnOff(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	s.MDMTurnOffFuncInvoked = true
+	s.mu.Unlock()
+	return s.MDMTurnOffFunc(ctx, uuid)
+}
+
+func (s *DataStore) NewActivity(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+	s.mu.Lock()
+	s.NewActivityFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewActivityFunc(ctx, user, activity)
+}
+
+func (s *DataStore) ListActivities(ctx context.Context, opt fleet.ListActivitiesOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListActivitiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListActivitiesFunc(ctx, opt)
+}
+
+func (s *DataStore) MarkActivitiesAsStreamed(ctx context.Context, activityIDs []uint) error {
+	s.mu.Lock()
+	s.MarkActivitiesAsStreamedFuncInvoked = true
+	s.mu.Unlock()
+	return s.MarkActivitiesAsStreamedFunc(ctx, activityIDs)
+}
+
+func (s *DataStore) ListHostUpcomingActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListHostUpcomingActivitiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostUpcomingActivitiesFunc(ctx, hostID, opt)
+}
+
+func (s *DataStore) ListHostPastActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListHostPastActivitiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostPastActivitiesFunc(ctx, hostID, opt)
+}
+
+func (s *DataStore) IsExecutionPendingForHost(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error) {
+	s.mu.Lock()
+	s.IsExecutionPendingForHostFuncInvoked = true
+	s.mu.Unlock()
+	return s.IsExecutionPendingForHostFunc(ctx, hostID, scriptID)
+}
+
+func (s *DataStore) NewAdminNotification(ctx context.Context, notification *fleet.AdminNotification) error {
+	s.mu.Lock()
+	s.NewAdminNotificationFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewAdminNotificationFunc(ctx, notification)
+}
+
+func (s *DataStore) ListAdminNotifications(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListAdminNotificationsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListAdminNotificationsFunc(ctx, userID, opts)
+}
+
+func (s *DataStore) CountUnreadAdminNotifications(ctx context.Context, userID uint) (int, error) {
+	s.mu.Lock()
+	s.CountUnreadAdminNotificationsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CountUnreadAdminNotificationsFunc(ctx, userID)
+}
+
+func (s *DataStore) MarkAdminNotificationsRead(ctx context.Context, userID uint, notificationIDs []uint) error {
+	s.mu.Lock()
+	s.MarkAdminNotificationsReadFuncInvoked = true
+	s.mu.Unlock()
+	return s.MarkAdminNotificationsReadFunc(ctx, userID, notificationIDs)
+}
+
+func (s *DataStore) DeleteAdminNotification(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteAdminNotificationFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteAdminNotificationFunc(ctx, id)
+}
+
+func (s *DataStore) ShouldSendStatistics(ctx context.Context, frequency time.Duration, config config.FleetConfig) (fleet.StatisticsPayload, bool, error) {
+	s.mu.Lock()
+	s.ShouldSendStatisticsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ShouldSendStatisticsFunc(ctx, frequency, config)
+}
+
+func (s *DataStore) RecordStatisticsSent(ctx context.Context) error {
+	s.mu.Lock()
+	s.RecordStatisticsSentFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordStatisticsSentFunc(ctx)
+}
+
+func (s *DataStore) CleanupStatistics(ctx context.Context) error {
+	s.mu.Lock()
+	s.CleanupStatisticsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupStatisticsFunc(ctx)
+}
+
+func (s *DataStore) IncrementUsageAnalyticsAPIRequests(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error {
+	s.mu.Lock()
+	s.IncrementUsageAnalyticsAPIRequestsFuncInvoked = true
+	s.mu.Unlock()
+	return s.IncrementUsageAnalyticsAPIRequestsFunc(ctx, counts)
+}
+
+func (s *DataStore) RecordUsageAnalyticsHostCount(ctx context.Context, date time.Time) error {
+	s.mu.Lock()
+	s.RecordUsageAnalyticsHostCountFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordUsageAnalyticsHostCountFunc(ctx, date)
+}
+
+func (s *DataStore) CleanupUsageAnalytics(ctx context.Context, olderThan time.Time) error {
+	s.mu.Lock()
+	s.CleanupUsageAnalyticsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupUsageAnalyticsFunc(ctx, olderThan)
+}
+
+func (s *DataStore) GetUsageAnalyticsDashboard(ctx context.Context, since time.Time, config config.FleetConfig) (*fleet.UsageAnalyticsDashboard, error) {
+	s.mu.Lock()
+	s.GetUsageAnalyticsDashboardFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetUsageAnalyticsDashboardFunc(ctx, since, config)
+}
+
+func (s *DataStore) ApplyPolicySpecs(ctx context.Context, authorID uint, specs []*fleet.PolicySpec) error {
+	s.mu.Lock()
+	s.ApplyPolicySpecsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyPolicySpecsFunc(ctx, authorID, specs)
+}
+
+func (s *DataStore) NewGlobalPolicy(ctx context.Context, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
+	s.mu.Lock()
+	s.NewGlobalPolicyFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewGlobalPolicyFunc(ctx, authorID, args)
+}
+
+func (s *DataStore) Policy(ctx context.Context, id uint) (*fleet.Policy, error) {
+	s.mu.Lock()
+	s.PolicyFuncInvoked = true
+	s.mu.Unlock()
+	return s.PolicyFunc(ctx, id)
+}
+
+func (s *DataStore) SavePolicy(ctx context.Context, p *fleet.Policy, shouldRemoveAllPolicyMemberships bool, removePolicyStats bool) error {
+	s.mu.Lock()
+	s.SavePolicyFuncInvoked = true
+	s.mu.Unlock()
+	return s.SavePolicyFunc(ctx, p, shouldRemoveAllPolicyMemberships, removePolicyStats)
+}
+
+func (s *DataStore) ListGlobalPolicies(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) {
+	s.mu.Lock()
+	s.ListGlobalPoliciesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListGlobalPoliciesFunc(ctx, opts)
+}
+
+func (s *DataStore) PoliciesByID(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
+	s.mu.Lock()
+	s.PoliciesByIDFuncInvoked = true
+	s.mu.Unlock()
+	return s.PoliciesByIDFunc(ctx, ids)
+}
+
+func (s *DataStore) DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error) {
+	s.mu.Lock()
+	s.DeleteGlobalPoliciesFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteGlobalPoliciesFunc(ctx, ids)
+}
+
+func (s *DataStore) CountPolicies(ctx context.Context, teamID *uint, matchQuery string) (int, error) {
+	s.mu.Lock()
+	s.CountPoliciesFuncInvoked = true
+	s.mu.Unlock()
+	return s.CountPoliciesFunc(ctx, teamID, matchQuery)
 }
 
 func (s *DataStore) UpdateHostPolicyCounts(ctx context.Context) error {
@@ -3771,6 +5478,27 @@ func (s *DataStore) UpdateHostPolicyCounts(ctx context.Context) error {
 	return s.UpdateHostPolicyCountsFunc(ctx)
 }
 
+func (s *DataStore) RecordPolicyComplianceHistory(ctx context.Context, date time.Time) error {
+	s.mu.Lock()
+	s.RecordPolicyComplianceHistoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordPolicyComplianceHistoryFunc(ctx, date)
+}
+
+func (s *DataStore) CleanupPolicyComplianceHistory(ctx context.Context, olderThan time.Time) error {
+	s.mu.Lock()
+	s.CleanupPolicyComplianceHistoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupPolicyComplianceHistoryFunc(ctx, olderThan)
+}
+
+func (s *DataStore) GetPolicyComplianceTrends(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) ([]fleet.PolicyComplianceDay, error) {
+	s.mu.Lock()
+	s.GetPolicyComplianceTrendsFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetPolicyComplianceTrendsFunc(ctx, opts)
+}
+
 func (s *DataStore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
 	s.mu.Lock()
 	s.PolicyQueriesForHostFuncInvoked = true
@@ -3841,6 +5569,13 @@ func (s *DataStore) CountSoftware(ctx context.Context, opt fleet.SoftwareListOpt
 	return s.CountSoftwareFunc(ctx, opt)
 }
 
+func (s *DataStore) StreamSoftware(ctx context.Context, opt fleet.SoftwareListOptions, batchSize int, fn func([]fleet.Software) error) error {
+	s.mu.Lock()
+	s.StreamSoftwareFuncInvoked = true
+	s.mu.Unlock()
+	return s.StreamSoftwareFunc(ctx, opt, batchSize, fn)
+}
+
 func (s *DataStore) DeleteSoftwareVulnerabilities(ctx context.Context, vulnerabilities []fleet.SoftwareVulnerability) error {
 	s.mu.Lock()
 	s.DeleteSoftwareVulnerabilitiesFuncInvoked = true
@@ -4002,11 +5737,11 @@ func (s *DataStore) InsertCronStats(ctx context.Context, statsType fleet.CronSta
 	return s.InsertCronStatsFunc(ctx, statsType, name, instance, status)
 }
 
-func (s *DataStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus) error {
+func (s *DataStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error {
 	s.mu.Lock()
 	s.UpdateCronStatsFuncInvoked = true
 	s.mu.Unlock()
-	return s.UpdateCronStatsFunc(ctx, id, status)
+	return s.UpdateCronStatsFunc(ctx, id, status, cronErrors)
 }
 
 func (s *DataStore) UpdateAllCronStatsForInstance(ctx context.Context, instance string, fromStatus fleet.CronStatsStatus, toStatus fleet.CronStatsStatus) error {
@@ -4023,6 +5758,34 @@ func (s *DataStore) CleanupCronStats(ctx context.Context) error {
 	return s.CleanupCronStatsFunc(ctx)
 }
 
+func (s *DataStore) ListLatestCronStats(ctx context.Context) ([]fleet.CronStats, error) {
+	s.mu.Lock()
+	s.ListLatestCronStatsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListLatestCronStatsFunc(ctx)
+}
+
+func (s *DataStore) IsCronSchedulePaused(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	s.IsCronSchedulePausedFuncInvoked = true
+	s.mu.Unlock()
+	return s.IsCronSchedulePausedFunc(ctx, name)
+}
+
+func (s *DataStore) SetCronSchedulePaused(ctx context.Context, name string, paused bool) error {
+	s.mu.Lock()
+	s.SetCronSchedulePausedFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetCronSchedulePausedFunc(ctx, name, paused)
+}
+
+func (s *DataStore) ListPausedCronSchedules(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	s.ListPausedCronSchedulesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPausedCronSchedulesFunc(ctx)
+}
+
 func (s *DataStore) UpdateQueryAggregatedStats(ctx context.Context) error {
 	s.mu.Lock()
 	s.UpdateQueryAggregatedStatsFuncInvoked = true
@@ -4100,6 +5863,27 @@ func (s *DataStore) UpdateHostSoftware(ctx context.Context, hostID uint, softwar
 	return s.UpdateHostSoftwareFunc(ctx, hostID, software)
 }
 
+func (s *DataStore) UpdateHostSoftwareFromSource(ctx context.Context, hostID uint, source fleet.SoftwareInventorySource, software []fleet.Software) (*fleet.UpdateHostSoftwareDBResult, error) {
+	s.mu.Lock()
+	s.UpdateHostSoftwareFromSourceFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateHostSoftwareFromSourceFunc(ctx, hostID, source, software)
+}
+
+func (s *DataStore) UpdateHostSoftwareUpdatedAt(ctx context.Context, hostID uint) error {
+	s.mu.Lock()
+	s.UpdateHostSoftwareUpdatedAtFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateHostSoftwareUpdatedAtFunc(ctx, hostID)
+}
+
+func (s *DataStore) DeleteHostSoftwareByBundleIdentifier(ctx context.Context, hostID uint, bundleIdentifier string) error {
+	s.mu.Lock()
+	s.DeleteHostSoftwareByBundleIdentifierFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteHostSoftwareByBundleIdentifierFunc(ctx, hostID, bundleIdentifier)
+}
+
 func (s *DataStore) UpdateHostSoftwareInstalledPaths(ctx context.Context, hostID uint, reported map[string]struct{}, mutationResults *fleet.UpdateHostSoftwareDBResult) error {
 	s.mu.Lock()
 	s.UpdateHostSoftwareInstalledPathsFuncInvoked = true
@@ -4233,6 +6017,41 @@ func (s *DataStore) GetHostDiskEncryptionKey(ctx context.Context, hostID uint) (
 	return s.GetHostDiskEncryptionKeyFunc(ctx, hostID)
 }
 
+func (s *DataStore) SetHostDiskEncryptionKeyRotationPending(ctx context.Context, hostID uint, cmdUUID string) (bool, error) {
+	s.mu.Lock()
+	s.SetHostDiskEncryptionKeyRotationPendingFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostDiskEncryptionKeyRotationPendingFunc(ctx, hostID, cmdUUID)
+}
+
+func (s *DataStore) UpdateHostDiskEncryptionKeyFromRotateResult(ctx context.Context, hostUUID string, cmdUUID string, encryptedBase64Key string) error {
+	s.mu.Lock()
+	s.UpdateHostDiskEncryptionKeyFromRotateResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateHostDiskEncryptionKeyFromRotateResultFunc(ctx, hostUUID, cmdUUID, encryptedBase64Key)
+}
+
+func (s *DataStore) SaveLUKSDiskEncryptionKey(ctx context.Context, hostID uint, passphrase string, keySlot *uint, clientError string) error {
+	s.mu.Lock()
+	s.SaveLUKSDiskEncryptionKeyFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveLUKSDiskEncryptionKeyFunc(ctx, hostID, passphrase, keySlot, clientError)
+}
+
+func (s *DataStore) GetHostLUKSDiskEncryptionKey(ctx context.Context, hostID uint) (*fleet.HostDiskEncryptionKey, error) {
+	s.mu.Lock()
+	s.GetHostLUKSDiskEncryptionKeyFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostLUKSDiskEncryptionKeyFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostLinuxDiskEncryptionEnforcement(ctx context.Context, hostID uint, status fleet.LinuxDiskEncryptionEnforcementStatus, detail string) error {
+	s.mu.Lock()
+	s.SetHostLinuxDiskEncryptionEnforcementFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostLinuxDiskEncryptionEnforcementFunc(ctx, hostID, status, detail)
+}
+
 func (s *DataStore) SetDiskEncryptionResetStatus(ctx context.Context, hostID uint, status bool) error {
 	s.mu.Lock()
 	s.SetDiskEncryptionResetStatusFuncInvoked = true
@@ -4247,6 +6066,13 @@ func (s *DataStore) GetHostCertAssociationsToExpire(ctx context.Context, expiryD
 	return s.GetHostCertAssociationsToExpireFunc(ctx, expiryDays, limit)
 }
 
+func (s *DataStore) GetHostSCEPIdentityAssociation(ctx context.Context, hostUUID string) (*fleet.SCEPIdentityAssociation, error) {
+	s.mu.Lock()
+	s.GetHostSCEPIdentityAssociationFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostSCEPIdentityAssociationFunc(ctx, hostUUID)
+}
+
 func (s *DataStore) SetCommandForPendingSCEPRenewal(ctx context.Context, assocs []fleet.SCEPIdentityAssociation, cmdUUID string) error {
 	s.mu.Lock()
 	s.SetCommandForPendingSCEPRenewalFuncInvoked = true
@@ -4261,6 +6087,34 @@ func (s *DataStore) CleanSCEPRenewRefs(ctx context.Context, hostUUID string) err
 	return s.CleanSCEPRenewRefsFunc(ctx, hostUUID)
 }
 
+func (s *DataStore) GetMDMAppleConfigProfileByHostCommandUUID(ctx context.Context, hostUUID string, cmdUUID string) (*fleet.MDMAppleConfigProfile, error) {
+	s.mu.Lock()
+	s.GetMDMAppleConfigProfileByHostCommandUUIDFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleConfigProfileByHostCommandUUIDFunc(ctx, hostUUID, cmdUUID)
+}
+
+func (s *DataStore) RecordHostMDMAppleProfileCertificate(ctx context.Context, hostUUID string, profileUUID string) error {
+	s.mu.Lock()
+	s.RecordHostMDMAppleProfileCertificateFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordHostMDMAppleProfileCertificateFunc(ctx, hostUUID, profileUUID)
+}
+
+func (s *DataStore) GetHostMDMAppleProfileCertificatesToRenew(ctx context.Context, installedBefore time.Time, limit int) ([]fleet.HostMDMAppleProfileCertificate, error) {
+	s.mu.Lock()
+	s.GetHostMDMAppleProfileCertificatesToRenewFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostMDMAppleProfileCertificatesToRenewFunc(ctx, installedBefore, limit)
+}
+
+func (s *DataStore) SetCommandForPendingProfileCertificateRenewal(ctx context.Context, certs []fleet.HostMDMAppleProfileCertificate, cmdUUID string) error {
+	s.mu.Lock()
+	s.SetCommandForPendingProfileCertificateRenewalFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetCommandForPendingProfileCertificateRenewalFunc(ctx, certs, cmdUUID)
+}
+
 func (s *DataStore) UpdateHostMDMProfilesVerification(ctx context.Context, host *fleet.Host, toVerify []string, toFail []string, toRetry []string) error {
 	s.mu.Lock()
 	s.UpdateHostMDMProfilesVerificationFuncInvoked = true
@@ -4289,11 +6143,11 @@ func (s *DataStore) GetHostMDMProfileRetryCountByCommandUUID(ctx context.Context
 	return s.GetHostMDMProfileRetryCountByCommandUUIDFunc(ctx, host, cmdUUID)
 }
 
-func (s *DataStore) SetOrUpdateHostOrbitInfo(ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool) error {
+func (s *DataStore) SetOrUpdateHostOrbitInfo(ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool, timezone sql.NullString) error {
 	s.mu.Lock()
 	s.SetOrUpdateHostOrbitInfoFuncInvoked = true
 	s.mu.Unlock()
-	return s.SetOrUpdateHostOrbitInfoFunc(ctx, hostID, version, desktopVersion, scriptsEnabled)
+	return s.SetOrUpdateHostOrbitInfoFunc(ctx, hostID, version, desktopVersion, scriptsEnabled, timezone)
 }
 
 func (s *DataStore) GetHostOrbitInfo(ctx context.Context, hostID uint) (*fleet.HostOrbitInfo, error) {
@@ -4317,6 +6171,41 @@ func (s *DataStore) ReplaceHostBatteries(ctx context.Context, id uint, mappings
 	return s.ReplaceHostBatteriesFunc(ctx, id, mappings)
 }
 
+func (s *DataStore) ReplaceHostCertificates(ctx context.Context, hostID uint, origin fleet.HostCertificateOrigin, certs []*fleet.HostCertificate) error {
+	s.mu.Lock()
+	s.ReplaceHostCertificatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReplaceHostCertificatesFunc(ctx, hostID, origin, certs)
+}
+
+func (s *DataStore) ListHostCertificates(ctx context.Context, hostID uint) ([]*fleet.HostCertificate, error) {
+	s.mu.Lock()
+	s.ListHostCertificatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostCertificatesFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetOrUpdateHostCloudInstance(ctx context.Context, hostID uint, instance *fleet.HostCloudInstance) error {
+	s.mu.Lock()
+	s.SetOrUpdateHostCloudInstanceFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetOrUpdateHostCloudInstanceFunc(ctx, hostID, instance)
+}
+
+func (s *DataStore) ListCertificateInventory(ctx context.Context, filter fleet.TeamFilter, opts fleet.CertificateInventoryListOptions) ([]*fleet.CertificateInventoryItem, int, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListCertificateInventoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCertificateInventoryFunc(ctx, filter, opts)
+}
+
+func (s *DataStore) ListExpiringCertificates(ctx context.Context, from time.Time, to time.Time, hostsThreshold uint) ([]*fleet.CertificateInventoryItem, error) {
+	s.mu.Lock()
+	s.ListExpiringCertificatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListExpiringCertificatesFunc(ctx, from, to, hostsThreshold)
+}
+
 func (s *DataStore) VerifyEnrollSecret(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
 	s.mu.Lock()
 	s.VerifyEnrollSecretFuncInvoked = true
@@ -4338,6 +6227,34 @@ func (s *DataStore) EnrollOrbit(ctx context.Context, isMDMEnabled bool, hostInfo
 	return s.EnrollOrbitFunc(ctx, isMDMEnabled, hostInfo, orbitNodeKey, teamID)
 }
 
+func (s *DataStore) GetHostEnrollmentCertificateBySHA256(ctx context.Context, sha256 string) (*fleet.HostEnrollmentCertificate, error) {
+	s.mu.Lock()
+	s.GetHostEnrollmentCertificateBySHA256FuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostEnrollmentCertificateBySHA256Func(ctx, sha256)
+}
+
+func (s *DataStore) RecordHostEnrollmentCertificate(ctx context.Context, cert *fleet.HostEnrollmentCertificate) error {
+	s.mu.Lock()
+	s.RecordHostEnrollmentCertificateFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordHostEnrollmentCertificateFunc(ctx, cert)
+}
+
+func (s *DataStore) ListHostEnrollmentCertificates(ctx context.Context, hostID uint) ([]*fleet.HostEnrollmentCertificate, error) {
+	s.mu.Lock()
+	s.ListHostEnrollmentCertificatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostEnrollmentCertificatesFunc(ctx, hostID)
+}
+
+func (s *DataStore) RevokeHostEnrollmentCertificates(ctx context.Context, hostID uint) (int, error) {
+	s.mu.Lock()
+	s.RevokeHostEnrollmentCertificatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.RevokeHostEnrollmentCertificatesFunc(ctx, hostID)
+}
+
 func (s *DataStore) SerialUpdateHost(ctx context.Context, host *fleet.Host) error {
 	s.mu.Lock()
 	s.SerialUpdateHostFuncInvoked = true
@@ -4380,6 +6297,69 @@ func (s *DataStore) ProcessList(ctx context.Context) ([]fleet.MySQLProcess, erro
 	return s.ProcessListFunc(ctx)
 }
 
+func (s *DataStore) ListDatabaseMaintenanceRuns(ctx context.Context) ([]*fleet.DatabaseMaintenanceRun, error) {
+	s.mu.Lock()
+	s.ListDatabaseMaintenanceRunsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListDatabaseMaintenanceRunsFunc(ctx)
+}
+
+func (s *DataStore) RecordDatabaseMaintenanceRun(ctx context.Context, run *fleet.DatabaseMaintenanceRun) error {
+	s.mu.Lock()
+	s.RecordDatabaseMaintenanceRunFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordDatabaseMaintenanceRunFunc(ctx, run)
+}
+
+func (s *DataStore) SnapshotTableSizes(ctx context.Context) error {
+	s.mu.Lock()
+	s.SnapshotTableSizesFuncInvoked = true
+	s.mu.Unlock()
+	return s.SnapshotTableSizesFunc(ctx)
+}
+
+func (s *DataStore) ListTableGrowth(ctx context.Context) ([]*fleet.TableGrowth, error) {
+	s.mu.Lock()
+	s.ListTableGrowthFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListTableGrowthFunc(ctx)
+}
+
+func (s *DataStore) AnalyzeTables(ctx context.Context, tables []string) error {
+	s.mu.Lock()
+	s.AnalyzeTablesFuncInvoked = true
+	s.mu.Unlock()
+	return s.AnalyzeTablesFunc(ctx, tables)
+}
+
+func (s *DataStore) OptimizeTables(ctx context.Context, tables []string) error {
+	s.mu.Lock()
+	s.OptimizeTablesFuncInvoked = true
+	s.mu.Unlock()
+	return s.OptimizeTablesFunc(ctx, tables)
+}
+
+func (s *DataStore) CleanupExpiredSessions(ctx context.Context, idleTimeout time.Duration, maxLifetime time.Duration) (int64, error) {
+	s.mu.Lock()
+	s.CleanupExpiredSessionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupExpiredSessionsFunc(ctx, idleTimeout, maxLifetime)
+}
+
+func (s *DataStore) CleanupExpiredInvites(ctx context.Context, createdBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	s.CleanupExpiredInvitesFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupExpiredInvitesFunc(ctx, createdBefore)
+}
+
+func (s *DataStore) CleanupOrphanedHostRows(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	s.CleanupOrphanedHostRowsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupOrphanedHostRowsFunc(ctx)
+}
+
 func (s *DataStore) ListWindowsUpdatesByHostID(ctx context.Context, hostID uint) ([]fleet.WindowsUpdate, error) {
 	s.mu.Lock()
 	s.ListWindowsUpdatesByHostIDFuncInvoked = true
@@ -4464,6 +6444,27 @@ func (s *DataStore) UpdateVulnerabilityHostCounts(ctx context.Context) error {
 	return s.UpdateVulnerabilityHostCountsFunc(ctx)
 }
 
+func (s *DataStore) UpdateHostCVEDetections(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	s.UpdateHostCVEDetectionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateHostCVEDetectionsFunc(ctx, now)
+}
+
+func (s *DataStore) ListVulnerabilitySLABreaches(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) ([]*fleet.VulnerabilitySLABreach, error) {
+	s.mu.Lock()
+	s.ListVulnerabilitySLABreachesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVulnerabilitySLABreachesFunc(ctx, opts)
+}
+
+func (s *DataStore) MarkVulnerabilitySLABreachesNotified(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) error {
+	s.mu.Lock()
+	s.MarkVulnerabilitySLABreachesNotifiedFuncInvoked = true
+	s.mu.Unlock()
+	return s.MarkVulnerabilitySLABreachesNotifiedFunc(ctx, opts)
+}
+
 func (s *DataStore) NewMDMAppleConfigProfile(ctx context.Context, p fleet.MDMAppleConfigProfile) (*fleet.MDMAppleConfigProfile, error) {
 	s.mu.Lock()
 	s.NewMDMAppleConfigProfileFuncInvoked = true
@@ -4716,6 +6717,13 @@ func (s *DataStore) GetNanoMDMEnrollment(ctx context.Context, id string) (*fleet
 	return s.GetNanoMDMEnrollmentFunc(ctx, id)
 }
 
+func (s *DataStore) ListNanoMDMUserEnrollmentDeviceIDs(ctx context.Context, deviceIDs []string) ([]string, error) {
+	s.mu.Lock()
+	s.ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListNanoMDMUserEnrollmentDeviceIDsFunc(ctx, deviceIDs)
+}
+
 func (s *DataStore) IncreasePolicyAutomationIteration(ctx context.Context, policyID uint) error {
 	s.mu.Lock()
 	s.IncreasePolicyAutomationIterationFuncInvoked = true
@@ -4730,6 +6738,20 @@ func (s *DataStore) OutdatedAutomationBatch(ctx context.Context) ([]fleet.Policy
 	return s.OutdatedAutomationBatchFunc(ctx)
 }
 
+func (s *DataStore) ListPolicyFailingHosts(ctx context.Context, policyID uint) ([]fleet.PolicySetHost, error) {
+	s.mu.Lock()
+	s.ListPolicyFailingHostsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPolicyFailingHostsFunc(ctx, policyID)
+}
+
+func (s *DataStore) StreamPolicyMembership(ctx context.Context, policyID uint, passes bool, batchSize int, fn func([]fleet.PolicySetHost) error) error {
+	s.mu.Lock()
+	s.StreamPolicyMembershipFuncInvoked = true
+	s.mu.Unlock()
+	return s.StreamPolicyMembershipFunc(ctx, policyID, passes, batchSize, fn)
+}
+
 func (s *DataStore) ListMDMAppleProfilesToInstall(ctx context.Context) ([]*fleet.MDMAppleProfilePayload, error) {
 	s.mu.Lock()
 	s.ListMDMAppleProfilesToInstallFuncInvoked = true
@@ -4748,126 +6770,315 @@ func (s *DataStore) BulkUpsertMDMAppleHostProfiles(ctx context.Context, payload
 	s.mu.Lock()
 	s.BulkUpsertMDMAppleHostProfilesFuncInvoked = true
 	s.mu.Unlock()
-	return s.BulkUpsertMDMAppleHostProfilesFunc(ctx, payload)
+	return s.BulkUpsertMDMAppleHostProfilesFunc(ctx, payload)
+}
+
+func (s *DataStore) BulkSetPendingMDMHostProfiles(ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string) error {
+	s.mu.Lock()
+	s.BulkSetPendingMDMHostProfilesFuncInvoked = true
+	s.mu.Unlock()
+	return s.BulkSetPendingMDMHostProfilesFunc(ctx, hostIDs, teamIDs, profileUUIDs, hostUUIDs)
+}
+
+func (s *DataStore) GetMDMAppleProfilesContents(ctx context.Context, profileUUIDs []string) (map[string]mobileconfig.Mobileconfig, error) {
+	s.mu.Lock()
+	s.GetMDMAppleProfilesContentsFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleProfilesContentsFunc(ctx, profileUUIDs)
+}
+
+func (s *DataStore) UpdateOrDeleteHostMDMAppleProfile(ctx context.Context, profile *fleet.HostMDMAppleProfile) error {
+	s.mu.Lock()
+	s.UpdateOrDeleteHostMDMAppleProfileFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateOrDeleteHostMDMAppleProfileFunc(ctx, profile)
+}
+
+func (s *DataStore) GetMDMAppleCommandRequestType(ctx context.Context, commandUUID string) (string, error) {
+	s.mu.Lock()
+	s.GetMDMAppleCommandRequestTypeFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleCommandRequestTypeFunc(ctx, commandUUID)
+}
+
+func (s *DataStore) ListIOSAndIPadOSToRefetch(ctx context.Context, interval time.Duration) ([]string, error) {
+	s.mu.Lock()
+	s.ListIOSAndIPadOSToRefetchFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListIOSAndIPadOSToRefetchFunc(ctx, interval)
+}
+
+func (s *DataStore) ListAppleHostsForMDMSoftwareRefetch(ctx context.Context, interval time.Duration) ([]string, error) {
+	s.mu.Lock()
+	s.ListAppleHostsForMDMSoftwareRefetchFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListAppleHostsForMDMSoftwareRefetchFunc(ctx, interval)
+}
+
+func (s *DataStore) ListHostsWithNotNowMDMAppleCommands(ctx context.Context, requestType string, retryInterval time.Duration, maxAge time.Duration) ([]string, error) {
+	s.mu.Lock()
+	s.ListHostsWithNotNowMDMAppleCommandsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostsWithNotNowMDMAppleCommandsFunc(ctx, requestType, retryInterval, maxAge)
+}
+
+func (s *DataStore) GetMDMAppleProfilesSummary(ctx context.Context, teamID *uint) (*fleet.MDMProfilesSummary, error) {
+	s.mu.Lock()
+	s.GetMDMAppleProfilesSummaryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleProfilesSummaryFunc(ctx, teamID)
+}
+
+func (s *DataStore) InsertMDMIdPAccount(ctx context.Context, account *fleet.MDMIdPAccount) error {
+	s.mu.Lock()
+	s.InsertMDMIdPAccountFuncInvoked = true
+	s.mu.Unlock()
+	return s.InsertMDMIdPAccountFunc(ctx, account)
+}
+
+func (s *DataStore) GetMDMIdPAccountByUUID(ctx context.Context, uuid string) (*fleet.MDMIdPAccount, error) {
+	s.mu.Lock()
+	s.GetMDMIdPAccountByUUIDFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMIdPAccountByUUIDFunc(ctx, uuid)
+}
+
+func (s *DataStore) GetMDMIdPAccountByEmail(ctx context.Context, email string) (*fleet.MDMIdPAccount, error) {
+	s.mu.Lock()
+	s.GetMDMIdPAccountByEmailFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMIdPAccountByEmailFunc(ctx, email)
+}
+
+func (s *DataStore) GetMDMAppleFileVaultSummary(ctx context.Context, teamID *uint) (*fleet.MDMAppleFileVaultSummary, error) {
+	s.mu.Lock()
+	s.GetMDMAppleFileVaultSummaryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleFileVaultSummaryFunc(ctx, teamID)
+}
+
+func (s *DataStore) InsertMDMAppleBootstrapPackage(ctx context.Context, bp *fleet.MDMAppleBootstrapPackage) error {
+	s.mu.Lock()
+	s.InsertMDMAppleBootstrapPackageFuncInvoked = true
+	s.mu.Unlock()
+	return s.InsertMDMAppleBootstrapPackageFunc(ctx, bp)
+}
+
+func (s *DataStore) CopyDefaultMDMAppleBootstrapPackage(ctx context.Context, ac *fleet.AppConfig, toTeamID uint) error {
+	s.mu.Lock()
+	s.CopyDefaultMDMAppleBootstrapPackageFuncInvoked = true
+	s.mu.Unlock()
+	return s.CopyDefaultMDMAppleBootstrapPackageFunc(ctx, ac, toTeamID)
+}
+
+func (s *DataStore) DeleteMDMAppleBootstrapPackage(ctx context.Context, teamID uint) error {
+	s.mu.Lock()
+	s.DeleteMDMAppleBootstrapPackageFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteMDMAppleBootstrapPackageFunc(ctx, teamID)
+}
+
+func (s *DataStore) GetMDMAppleBootstrapPackageMeta(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackage, error) {
+	s.mu.Lock()
+	s.GetMDMAppleBootstrapPackageMetaFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleBootstrapPackageMetaFunc(ctx, teamID)
+}
+
+func (s *DataStore) GetMDMAppleBootstrapPackageBytes(ctx context.Context, token string) (*fleet.MDMAppleBootstrapPackage, error) {
+	s.mu.Lock()
+	s.GetMDMAppleBootstrapPackageBytesFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleBootstrapPackageBytesFunc(ctx, token)
+}
+
+func (s *DataStore) GetMDMAppleBootstrapPackageSummary(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackageSummary, error) {
+	s.mu.Lock()
+	s.GetMDMAppleBootstrapPackageSummaryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMAppleBootstrapPackageSummaryFunc(ctx, teamID)
+}
+
+func (s *DataStore) RecordHostBootstrapPackage(ctx context.Context, commandUUID string, hostUUID string) error {
+	s.mu.Lock()
+	s.RecordHostBootstrapPackageFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordHostBootstrapPackageFunc(ctx, commandUUID, hostUUID)
+}
+
+func (s *DataStore) GetHostMDMMacOSSetup(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
+	s.mu.Lock()
+	s.GetHostMDMMacOSSetupFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostMDMMacOSSetupFunc(ctx, hostID)
+}
+
+func (s *DataStore) RecordHostFleetdInstall(ctx context.Context, commandUUID string, hostUUID string, retries uint) error {
+	s.mu.Lock()
+	s.RecordHostFleetdInstallFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordHostFleetdInstallFunc(ctx, commandUUID, hostUUID, retries)
+}
+
+func (s *DataStore) GetHostMDMFleetdInstall(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+	s.mu.Lock()
+	s.GetHostMDMFleetdInstallFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostMDMFleetdInstallFunc(ctx, hostUUID)
+}
+
+func (s *DataStore) SetOrUpdateHostMDMAppleSecurityInfo(ctx context.Context, hostID uint, info *fleet.HostMDMAppleSecurityInfo) error {
+	s.mu.Lock()
+	s.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetOrUpdateHostMDMAppleSecurityInfoFunc(ctx, hostID, info)
+}
+
+func (s *DataStore) GetHostMDMAppleSecurityInfo(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+	s.mu.Lock()
+	s.GetHostMDMAppleSecurityInfoFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostMDMAppleSecurityInfoFunc(ctx, hostID)
+}
+
+func (s *DataStore) NewHostLostMode(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings, enableCommandUUID string) error {
+	s.mu.Lock()
+	s.NewHostLostModeFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostLostModeFunc(ctx, hostID, settings, enableCommandUUID)
+}
+
+func (s *DataStore) GetHostLostMode(ctx context.Context, hostID uint) (*fleet.HostLostMode, error) {
+	s.mu.Lock()
+	s.GetHostLostModeFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostLostModeFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostLostModePendingDisable(ctx context.Context, hostID uint, disableCommandUUID string) error {
+	s.mu.Lock()
+	s.SetHostLostModePendingDisableFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostLostModePendingDisableFunc(ctx, hostID, disableCommandUUID)
 }
 
-func (s *DataStore) BulkSetPendingMDMHostProfiles(ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string) error {
+func (s *DataStore) UpdateHostLostModeFromAppleMDMResult(ctx context.Context, hostUUID string, cmdUUID string, requestType string, succeeded bool) error {
 	s.mu.Lock()
-	s.BulkSetPendingMDMHostProfilesFuncInvoked = true
+	s.UpdateHostLostModeFromAppleMDMResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.BulkSetPendingMDMHostProfilesFunc(ctx, hostIDs, teamIDs, profileUUIDs, hostUUIDs)
+	return s.UpdateHostLostModeFromAppleMDMResultFunc(ctx, hostUUID, cmdUUID, requestType, succeeded)
 }
 
-func (s *DataStore) GetMDMAppleProfilesContents(ctx context.Context, profileUUIDs []string) (map[string]mobileconfig.Mobileconfig, error) {
+func (s *DataStore) NewHostLocation(ctx context.Context, loc *fleet.HostMDMAppleLocation) error {
 	s.mu.Lock()
-	s.GetMDMAppleProfilesContentsFuncInvoked = true
+	s.NewHostLocationFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleProfilesContentsFunc(ctx, profileUUIDs)
+	return s.NewHostLocationFunc(ctx, loc)
 }
 
-func (s *DataStore) UpdateOrDeleteHostMDMAppleProfile(ctx context.Context, profile *fleet.HostMDMAppleProfile) error {
+func (s *DataStore) GetHostLastLocation(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error) {
 	s.mu.Lock()
-	s.UpdateOrDeleteHostMDMAppleProfileFuncInvoked = true
+	s.GetHostLastLocationFuncInvoked = true
 	s.mu.Unlock()
-	return s.UpdateOrDeleteHostMDMAppleProfileFunc(ctx, profile)
+	return s.GetHostLastLocationFunc(ctx, hostID)
 }
 
-func (s *DataStore) GetMDMAppleCommandRequestType(ctx context.Context, commandUUID string) (string, error) {
+func (s *DataStore) SetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostUUID string, bypassCode string) error {
 	s.mu.Lock()
-	s.GetMDMAppleCommandRequestTypeFuncInvoked = true
+	s.SetHostMDMAppleActivationLockBypassCodeFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleCommandRequestTypeFunc(ctx, commandUUID)
+	return s.SetHostMDMAppleActivationLockBypassCodeFunc(ctx, hostUUID, bypassCode)
 }
 
-func (s *DataStore) GetMDMAppleProfilesSummary(ctx context.Context, teamID *uint) (*fleet.MDMProfilesSummary, error) {
+func (s *DataStore) GetHostMDMAppleActivationLockBypassCode(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error) {
 	s.mu.Lock()
-	s.GetMDMAppleProfilesSummaryFuncInvoked = true
+	s.GetHostMDMAppleActivationLockBypassCodeFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleProfilesSummaryFunc(ctx, teamID)
+	return s.GetHostMDMAppleActivationLockBypassCodeFunc(ctx, hostID)
 }
 
-func (s *DataStore) InsertMDMIdPAccount(ctx context.Context, account *fleet.MDMIdPAccount) error {
+func (s *DataStore) SetHostMDMAppleActivationLockBypassCodePendingClear(ctx context.Context, hostID uint, clearCommandUUID string) error {
 	s.mu.Lock()
-	s.InsertMDMIdPAccountFuncInvoked = true
+	s.SetHostMDMAppleActivationLockBypassCodePendingClearFuncInvoked = true
 	s.mu.Unlock()
-	return s.InsertMDMIdPAccountFunc(ctx, account)
+	return s.SetHostMDMAppleActivationLockBypassCodePendingClearFunc(ctx, hostID, clearCommandUUID)
 }
 
-func (s *DataStore) GetMDMIdPAccountByUUID(ctx context.Context, uuid string) (*fleet.MDMIdPAccount, error) {
+func (s *DataStore) UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(ctx context.Context, hostUUID string, cmdUUID string, succeeded bool) error {
 	s.mu.Lock()
-	s.GetMDMIdPAccountByUUIDFuncInvoked = true
+	s.UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMIdPAccountByUUIDFunc(ctx, uuid)
+	return s.UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFunc(ctx, hostUUID, cmdUUID, succeeded)
 }
 
-func (s *DataStore) GetMDMIdPAccountByEmail(ctx context.Context, email string) (*fleet.MDMIdPAccount, error) {
+func (s *DataStore) ListHostsForAppleRecoveryLockRotation(ctx context.Context, rotateBefore time.Time, retryBefore time.Time, limit int) ([]*fleet.HostMDMAppleRecoveryLockPassword, error) {
 	s.mu.Lock()
-	s.GetMDMIdPAccountByEmailFuncInvoked = true
+	s.ListHostsForAppleRecoveryLockRotationFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMIdPAccountByEmailFunc(ctx, email)
+	return s.ListHostsForAppleRecoveryLockRotationFunc(ctx, rotateBefore, retryBefore, limit)
 }
 
-func (s *DataStore) GetMDMAppleFileVaultSummary(ctx context.Context, teamID *uint) (*fleet.MDMAppleFileVaultSummary, error) {
+func (s *DataStore) SetHostMDMAppleRecoveryLockPending(ctx context.Context, hostID uint, newPassword string, setCmdUUID string) error {
 	s.mu.Lock()
-	s.GetMDMAppleFileVaultSummaryFuncInvoked = true
+	s.SetHostMDMAppleRecoveryLockPendingFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleFileVaultSummaryFunc(ctx, teamID)
+	return s.SetHostMDMAppleRecoveryLockPendingFunc(ctx, hostID, newPassword, setCmdUUID)
 }
 
-func (s *DataStore) InsertMDMAppleBootstrapPackage(ctx context.Context, bp *fleet.MDMAppleBootstrapPackage) error {
+func (s *DataStore) UpdateHostMDMAppleRecoveryLockFromSetResult(ctx context.Context, hostUUID string, cmdUUID string, errorMessage string) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
 	s.mu.Lock()
-	s.InsertMDMAppleBootstrapPackageFuncInvoked = true
+	s.UpdateHostMDMAppleRecoveryLockFromSetResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.InsertMDMAppleBootstrapPackageFunc(ctx, bp)
+	return s.UpdateHostMDMAppleRecoveryLockFromSetResultFunc(ctx, hostUUID, cmdUUID, errorMessage)
 }
 
-func (s *DataStore) CopyDefaultMDMAppleBootstrapPackage(ctx context.Context, ac *fleet.AppConfig, toTeamID uint) error {
+func (s *DataStore) SetHostMDMAppleRecoveryLockPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error {
 	s.mu.Lock()
-	s.CopyDefaultMDMAppleBootstrapPackageFuncInvoked = true
+	s.SetHostMDMAppleRecoveryLockPendingVerifyFuncInvoked = true
 	s.mu.Unlock()
-	return s.CopyDefaultMDMAppleBootstrapPackageFunc(ctx, ac, toTeamID)
+	return s.SetHostMDMAppleRecoveryLockPendingVerifyFunc(ctx, hostID, verifyCmdUUID)
 }
 
-func (s *DataStore) DeleteMDMAppleBootstrapPackage(ctx context.Context, teamID uint) error {
+func (s *DataStore) UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx context.Context, hostUUID string, cmdUUID string, verified bool) error {
 	s.mu.Lock()
-	s.DeleteMDMAppleBootstrapPackageFuncInvoked = true
+	s.UpdateHostMDMAppleRecoveryLockFromVerifyResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.DeleteMDMAppleBootstrapPackageFunc(ctx, teamID)
+	return s.UpdateHostMDMAppleRecoveryLockFromVerifyResultFunc(ctx, hostUUID, cmdUUID, verified)
 }
 
-func (s *DataStore) GetMDMAppleBootstrapPackageMeta(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackage, error) {
+func (s *DataStore) GetHostMDMAppleRecoveryLockPassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
 	s.mu.Lock()
-	s.GetMDMAppleBootstrapPackageMetaFuncInvoked = true
+	s.GetHostMDMAppleRecoveryLockPasswordFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleBootstrapPackageMetaFunc(ctx, teamID)
+	return s.GetHostMDMAppleRecoveryLockPasswordFunc(ctx, hostID)
 }
 
-func (s *DataStore) GetMDMAppleBootstrapPackageBytes(ctx context.Context, token string) (*fleet.MDMAppleBootstrapPackage, error) {
+func (s *DataStore) UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx context.Context, hostUUID string, cmdUUID string, errorMessage string) (*fleet.HostMDMAppleFirmwarePassword, error) {
 	s.mu.Lock()
-	s.GetMDMAppleBootstrapPackageBytesFuncInvoked = true
+	s.UpdateHostMDMAppleFirmwarePasswordFromSetResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleBootstrapPackageBytesFunc(ctx, token)
+	return s.UpdateHostMDMAppleFirmwarePasswordFromSetResultFunc(ctx, hostUUID, cmdUUID, errorMessage)
 }
 
-func (s *DataStore) GetMDMAppleBootstrapPackageSummary(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackageSummary, error) {
+func (s *DataStore) SetHostMDMAppleFirmwarePasswordPendingVerify(ctx context.Context, hostID uint, verifyCmdUUID string) error {
 	s.mu.Lock()
-	s.GetMDMAppleBootstrapPackageSummaryFuncInvoked = true
+	s.SetHostMDMAppleFirmwarePasswordPendingVerifyFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetMDMAppleBootstrapPackageSummaryFunc(ctx, teamID)
+	return s.SetHostMDMAppleFirmwarePasswordPendingVerifyFunc(ctx, hostID, verifyCmdUUID)
 }
 
-func (s *DataStore) RecordHostBootstrapPackage(ctx context.Context, commandUUID string, hostUUID string) error {
+func (s *DataStore) UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx context.Context, hostUUID string, cmdUUID string, verified bool) error {
 	s.mu.Lock()
-	s.RecordHostBootstrapPackageFuncInvoked = true
+	s.UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFuncInvoked = true
 	s.mu.Unlock()
-	return s.RecordHostBootstrapPackageFunc(ctx, commandUUID, hostUUID)
+	return s.UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFunc(ctx, hostUUID, cmdUUID, verified)
 }
 
-func (s *DataStore) GetHostMDMMacOSSetup(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
+func (s *DataStore) GetHostMDMAppleFirmwarePassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error) {
 	s.mu.Lock()
-	s.GetHostMDMMacOSSetupFuncInvoked = true
+	s.GetHostMDMAppleFirmwarePasswordFuncInvoked = true
 	s.mu.Unlock()
-	return s.GetHostMDMMacOSSetupFunc(ctx, hostID)
+	return s.GetHostMDMAppleFirmwarePasswordFunc(ctx, hostID)
 }
 
 func (s *DataStore) MDMGetEULAMetadata(ctx context.Context) (*fleet.MDMEULA, error) {
@@ -5150,6 +7361,13 @@ func (s *DataStore) ListMDMConfigProfiles(ctx context.Context, teamID *uint, opt
 	return s.ListMDMConfigProfilesFunc(ctx, teamID, opt)
 }
 
+func (s *DataStore) PreviewMDMProfileTargets(ctx context.Context, teamID *uint, platform string, labels []fleet.ConfigurationProfileLabel) (*fleet.MDMProfileTargetsPreview, error) {
+	s.mu.Lock()
+	s.PreviewMDMProfileTargetsFuncInvoked = true
+	s.mu.Unlock()
+	return s.PreviewMDMProfileTargetsFunc(ctx, teamID, platform, labels)
+}
+
 func (s *DataStore) ResendHostMDMProfile(ctx context.Context, hostUUID string, profileUUID string) error {
 	s.mu.Lock()
 	s.ResendHostMDMProfileFuncInvoked = true
@@ -5157,6 +7375,83 @@ func (s *DataStore) ResendHostMDMProfile(ctx context.Context, hostUUID string, p
 	return s.ResendHostMDMProfileFunc(ctx, hostUUID, profileUUID)
 }
 
+func (s *DataStore) NewMDMProfileRedeliveryCampaign(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	s.mu.Lock()
+	s.NewMDMProfileRedeliveryCampaignFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewMDMProfileRedeliveryCampaignFunc(ctx, campaign)
+}
+
+func (s *DataStore) MDMProfileRedeliveryCampaign(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	s.mu.Lock()
+	s.MDMProfileRedeliveryCampaignFuncInvoked = true
+	s.mu.Unlock()
+	return s.MDMProfileRedeliveryCampaignFunc(ctx, id)
+}
+
+func (s *DataStore) ListMDMProfileRedeliveryCampaigns(ctx context.Context, opts fleet.MDMProfileRedeliveryCampaignListOptions) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+	s.mu.Lock()
+	s.ListMDMProfileRedeliveryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListMDMProfileRedeliveryCampaignsFunc(ctx, opts)
+}
+
+func (s *DataStore) ListRunningMDMProfileRedeliveryCampaigns(ctx context.Context) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+	s.mu.Lock()
+	s.ListRunningMDMProfileRedeliveryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListRunningMDMProfileRedeliveryCampaignsFunc(ctx)
+}
+
+func (s *DataStore) UpdateMDMProfileRedeliveryCampaign(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) error {
+	s.mu.Lock()
+	s.UpdateMDMProfileRedeliveryCampaignFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateMDMProfileRedeliveryCampaignFunc(ctx, campaign)
+}
+
+func (s *DataStore) RedeliverMDMProfileCampaignBatch(ctx context.Context, campaignID uint, batchSize uint, now time.Time) (uint, error) {
+	s.mu.Lock()
+	s.RedeliverMDMProfileCampaignBatchFuncInvoked = true
+	s.mu.Unlock()
+	return s.RedeliverMDMProfileCampaignBatchFunc(ctx, campaignID, batchSize, now)
+}
+
+func (s *DataStore) GetMDMProfileRollout(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error) {
+	s.mu.Lock()
+	s.GetMDMProfileRolloutFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMProfileRolloutFunc(ctx, profileUUID)
+}
+
+func (s *DataStore) ListActiveMDMProfileRollouts(ctx context.Context) ([]*fleet.MDMProfileRollout, error) {
+	s.mu.Lock()
+	s.ListActiveMDMProfileRolloutsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListActiveMDMProfileRolloutsFunc(ctx)
+}
+
+func (s *DataStore) GetMDMProfileRolloutResults(ctx context.Context, profileUUID string) (*fleet.MDMProfileRolloutResults, error) {
+	s.mu.Lock()
+	s.GetMDMProfileRolloutResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMProfileRolloutResultsFunc(ctx, profileUUID)
+}
+
+func (s *DataStore) UpdateMDMProfileRolloutStatus(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error {
+	s.mu.Lock()
+	s.UpdateMDMProfileRolloutStatusFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateMDMProfileRolloutStatusFunc(ctx, profileUUID, status, reason)
+}
+
+func (s *DataStore) CleanupMDMProfileRollouts(ctx context.Context) error {
+	s.mu.Lock()
+	s.CleanupMDMProfileRolloutsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupMDMProfileRolloutsFunc(ctx)
+}
+
 func (s *DataStore) GetHostMDMProfileInstallStatus(ctx context.Context, hostUUID string, profileUUID string) (fleet.MDMDeliveryStatus, error) {
 	s.mu.Lock()
 	s.GetHostMDMProfileInstallStatusFuncInvoked = true
@@ -5185,6 +7480,13 @@ func (s *DataStore) GetMDMWindowsBitLockerSummary(ctx context.Context, teamID *u
 	return s.GetMDMWindowsBitLockerSummaryFunc(ctx, teamID)
 }
 
+func (s *DataStore) GetMDMLinuxDiskEncryptionSummary(ctx context.Context, teamID *uint) (*fleet.MDMLinuxDiskEncryptionSummary, error) {
+	s.mu.Lock()
+	s.GetMDMLinuxDiskEncryptionSummaryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetMDMLinuxDiskEncryptionSummaryFunc(ctx, teamID)
+}
+
 func (s *DataStore) GetMDMWindowsBitLockerStatus(ctx context.Context, host *fleet.Host) (*fleet.HostMDMDiskEncryption, error) {
 	s.mu.Lock()
 	s.GetMDMWindowsBitLockerStatusFuncInvoked = true
@@ -5269,6 +7571,167 @@ func (s *DataStore) SetOrUpdateMDMAppleDeclaration(ctx context.Context, declarat
 	return s.SetOrUpdateMDMAppleDeclarationFunc(ctx, declaration)
 }
 
+func (s *DataStore) NewHostLogCollection(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error) {
+	s.mu.Lock()
+	s.NewHostLogCollectionFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostLogCollectionFunc(ctx, payload)
+}
+
+func (s *DataStore) GetHostLogCollection(ctx context.Context, execID string) (*fleet.HostLogCollection, error) {
+	s.mu.Lock()
+	s.GetHostLogCollectionFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostLogCollectionFunc(ctx, execID)
+}
+
+func (s *DataStore) GetHostLogCollectionData(ctx context.Context, execID string) ([]byte, error) {
+	s.mu.Lock()
+	s.GetHostLogCollectionDataFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostLogCollectionDataFunc(ctx, execID)
+}
+
+func (s *DataStore) ListHostLogCollections(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+	s.mu.Lock()
+	s.ListHostLogCollectionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostLogCollectionsFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListPendingHostLogCollections(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+	s.mu.Lock()
+	s.ListPendingHostLogCollectionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPendingHostLogCollectionsFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostLogCollectionResult(ctx context.Context, result *fleet.HostLogCollectionResultPayload) (*fleet.HostLogCollection, error) {
+	s.mu.Lock()
+	s.SetHostLogCollectionResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostLogCollectionResultFunc(ctx, result)
+}
+
+func (s *DataStore) NewHostFileRetrieval(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error) {
+	s.mu.Lock()
+	s.NewHostFileRetrievalFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostFileRetrievalFunc(ctx, payload)
+}
+
+func (s *DataStore) GetHostFileRetrieval(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error) {
+	s.mu.Lock()
+	s.GetHostFileRetrievalFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostFileRetrievalFunc(ctx, execID)
+}
+
+func (s *DataStore) GetHostFileRetrievalData(ctx context.Context, execID string) ([]byte, error) {
+	s.mu.Lock()
+	s.GetHostFileRetrievalDataFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostFileRetrievalDataFunc(ctx, execID)
+}
+
+func (s *DataStore) ListHostFileRetrievals(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+	s.mu.Lock()
+	s.ListHostFileRetrievalsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostFileRetrievalsFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListPendingHostFileRetrievals(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+	s.mu.Lock()
+	s.ListPendingHostFileRetrievalsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPendingHostFileRetrievalsFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostFileRetrievalResult(ctx context.Context, result *fleet.HostFileRetrievalResultPayload) (*fleet.HostFileRetrieval, error) {
+	s.mu.Lock()
+	s.SetHostFileRetrievalResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostFileRetrievalResultFunc(ctx, result)
+}
+
+func (s *DataStore) NewHostPprofCapture(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error) {
+	s.mu.Lock()
+	s.NewHostPprofCaptureFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostPprofCaptureFunc(ctx, payload)
+}
+
+func (s *DataStore) GetHostPprofCapture(ctx context.Context, execID string) (*fleet.HostPprofCapture, error) {
+	s.mu.Lock()
+	s.GetHostPprofCaptureFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostPprofCaptureFunc(ctx, execID)
+}
+
+func (s *DataStore) GetHostPprofCaptureData(ctx context.Context, execID string) ([]byte, error) {
+	s.mu.Lock()
+	s.GetHostPprofCaptureDataFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostPprofCaptureDataFunc(ctx, execID)
+}
+
+func (s *DataStore) ListHostPprofCaptures(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+	s.mu.Lock()
+	s.ListHostPprofCapturesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostPprofCapturesFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListPendingHostPprofCaptures(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+	s.mu.Lock()
+	s.ListPendingHostPprofCapturesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPendingHostPprofCapturesFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostPprofCaptureResult(ctx context.Context, result *fleet.HostPprofCaptureResultPayload) (*fleet.HostPprofCapture, error) {
+	s.mu.Lock()
+	s.SetHostPprofCaptureResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostPprofCaptureResultFunc(ctx, result)
+}
+
+func (s *DataStore) NewHostRemoteQuery(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error) {
+	s.mu.Lock()
+	s.NewHostRemoteQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostRemoteQueryFunc(ctx, payload)
+}
+
+func (s *DataStore) GetHostRemoteQuery(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error) {
+	s.mu.Lock()
+	s.GetHostRemoteQueryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostRemoteQueryFunc(ctx, execID)
+}
+
+func (s *DataStore) ListHostRemoteQueries(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+	s.mu.Lock()
+	s.ListHostRemoteQueriesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostRemoteQueriesFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListPendingHostRemoteQueries(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+	s.mu.Lock()
+	s.ListPendingHostRemoteQueriesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListPendingHostRemoteQueriesFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostRemoteQueryResult(ctx context.Context, result *fleet.HostRemoteQueryResultPayload) (*fleet.HostRemoteQuery, error) {
+	s.mu.Lock()
+	s.SetHostRemoteQueryResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetHostRemoteQueryResultFunc(ctx, result)
+}
+
 func (s *DataStore) NewHostScriptExecutionRequest(ctx context.Context, request *fleet.HostScriptRequestPayload) (*fleet.HostScriptResult, error) {
 	s.mu.Lock()
 	s.NewHostScriptExecutionRequestFuncInvoked = true
@@ -5353,6 +7816,76 @@ func (s *DataStore) BatchSetScripts(ctx context.Context, tmID *uint, scripts []*
 	return s.BatchSetScriptsFunc(ctx, tmID, scripts)
 }
 
+func (s *DataStore) SetScriptSharedWithAllTeams(ctx context.Context, scriptID uint, shared bool) error {
+	s.mu.Lock()
+	s.SetScriptSharedWithAllTeamsFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetScriptSharedWithAllTeamsFunc(ctx, scriptID, shared)
+}
+
+func (s *DataStore) SetMDMConfigProfileSharedWithAllTeams(ctx context.Context, profileUUID string, shared bool) error {
+	s.mu.Lock()
+	s.SetMDMConfigProfileSharedWithAllTeamsFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetMDMConfigProfileSharedWithAllTeamsFunc(ctx, profileUUID, shared)
+}
+
+func (s *DataStore) GetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error) {
+	s.mu.Lock()
+	s.GetTeamSharedLibraryOptOutsFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetTeamSharedLibraryOptOutsFunc(ctx, teamID)
+}
+
+func (s *DataStore) SetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) error {
+	s.mu.Lock()
+	s.SetTeamSharedLibraryOptOutsFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetTeamSharedLibraryOptOutsFunc(ctx, teamID, optOuts)
+}
+
+func (s *DataStore) NewScriptSchedule(ctx context.Context, schedule *fleet.ScriptSchedule) (*fleet.ScriptSchedule, error) {
+	s.mu.Lock()
+	s.NewScriptScheduleFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewScriptScheduleFunc(ctx, schedule)
+}
+
+func (s *DataStore) ScriptSchedule(ctx context.Context, id uint) (*fleet.ScriptSchedule, error) {
+	s.mu.Lock()
+	s.ScriptScheduleFuncInvoked = true
+	s.mu.Unlock()
+	return s.ScriptScheduleFunc(ctx, id)
+}
+
+func (s *DataStore) ListScriptSchedules(ctx context.Context, teamID *uint) ([]*fleet.ScriptSchedule, error) {
+	s.mu.Lock()
+	s.ListScriptSchedulesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListScriptSchedulesFunc(ctx, teamID)
+}
+
+func (s *DataStore) DeleteScriptSchedule(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteScriptScheduleFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteScriptScheduleFunc(ctx, id)
+}
+
+func (s *DataStore) ListScriptScheduleHosts(ctx context.Context) ([]*fleet.ScriptScheduleHost, error) {
+	s.mu.Lock()
+	s.ListScriptScheduleHostsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListScriptScheduleHostsFunc(ctx)
+}
+
+func (s *DataStore) NewScheduledHostScriptExecutionRequest(ctx context.Context, request *fleet.HostScriptRequestPayload, scheduleID uint, localDate string) (*fleet.HostScriptResult, error) {
+	s.mu.Lock()
+	s.NewScheduledHostScriptExecutionRequestFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewScheduledHostScriptExecutionRequestFunc(ctx, request, scheduleID, localDate)
+}
+
 func (s *DataStore) GetHostLockWipeStatus(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 	s.mu.Lock()
 	s.GetHostLockWipeStatusFuncInvoked = true
@@ -5388,6 +7921,27 @@ func (s *DataStore) CleanMacOSMDMLock(ctx context.Context, hostUUID string) erro
 	return s.CleanMacOSMDMLockFunc(ctx, hostUUID)
 }
 
+func (s *DataStore) NewHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string, userID uint) error {
+	s.mu.Lock()
+	s.NewHostLockWipeApprovalRequestFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewHostLockWipeApprovalRequestFunc(ctx, hostID, action, userID)
+}
+
+func (s *DataStore) GetHostLockWipeApprovalRequest(ctx context.Context, hostID uint, action string) (*fleet.HostLockWipeApprovalRequest, error) {
+	s.mu.Lock()
+	s.GetHostLockWipeApprovalRequestFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetHostLockWipeApprovalRequestFunc(ctx, hostID, action)
+}
+
+func (s *DataStore) ClaimHostLockWipeApprovalRequest(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error) {
+	s.mu.Lock()
+	s.ClaimHostLockWipeApprovalRequestFuncInvoked = true
+	s.mu.Unlock()
+	return s.ClaimHostLockWipeApprovalRequestFunc(ctx, req)
+}
+
 func (s *DataStore) CleanupUnusedScriptContents(ctx context.Context) error {
 	s.mu.Lock()
 	s.CleanupUnusedScriptContentsFuncInvoked = true
@@ -5409,6 +7963,20 @@ func (s *DataStore) WipeHostViaScript(ctx context.Context, request *fleet.HostSc
 	return s.WipeHostViaScriptFunc(ctx, request, hostFleetPlatform)
 }
 
+func (s *DataStore) IsolateHostViaScript(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error {
+	s.mu.Lock()
+	s.IsolateHostViaScriptFuncInvoked = true
+	s.mu.Unlock()
+	return s.IsolateHostViaScriptFunc(ctx, request, hostFleetPlatform)
+}
+
+func (s *DataStore) UnisolateHostViaScript(ctx context.Context, request *fleet.HostScriptRequestPayload, hostFleetPlatform string) error {
+	s.mu.Lock()
+	s.UnisolateHostViaScriptFuncInvoked = true
+	s.mu.Unlock()
+	return s.UnisolateHostViaScriptFunc(ctx, request, hostFleetPlatform)
+}
+
 func (s *DataStore) WipeHostViaWindowsMDM(ctx context.Context, host *fleet.Host, cmd *fleet.MDMWindowsCommand) error {
 	s.mu.Lock()
 	s.WipeHostViaWindowsMDMFuncInvoked = true
@@ -15,6 +15,10 @@ type WriteResultFunc func(result fleet.DistributedQueryResult) error
 
 type ReadChannelFunc func(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error)
 
+type ReadChannelFromCursorFunc func(ctx context.Context, query fleet.DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error)
+
+type DetachCampaignFunc func(ctx context.Context, campaignID uint) (bool, error)
+
 type HealthCheckFunc func() error
 
 type QueryResultStore struct {
@@ -24,6 +28,12 @@ type QueryResultStore struct {
 	ReadChannelFunc        ReadChannelFunc
 	ReadChannelFuncInvoked bool
 
+	ReadChannelFromCursorFunc        ReadChannelFromCursorFunc
+	ReadChannelFromCursorFuncInvoked bool
+
+	DetachCampaignFunc        DetachCampaignFunc
+	DetachCampaignFuncInvoked bool
+
 	HealthCheckFunc        HealthCheckFunc
 	HealthCheckFuncInvoked bool
 
@@ -44,6 +54,20 @@ func (s *QueryResultStore) ReadChannel(ctx context.Context, query fleet.Distribu
 	return s.ReadChannelFunc(ctx, query)
 }
 
+func (s *QueryResultStore) ReadChannelFromCursor(ctx context.Context, query fleet.DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error) {
+	s.mu.Lock()
+	s.ReadChannelFromCursorFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReadChannelFromCursorFunc(ctx, query, cursor)
+}
+
+func (s *QueryResultStore) DetachCampaign(ctx context.Context, campaignID uint) (bool, error) {
+	s.mu.Lock()
+	s.DetachCampaignFuncInvoked = true
+	s.mu.Unlock()
+	return s.DetachCampaignFunc(ctx, campaignID)
+}
+
 func (s *QueryResultStore) HealthCheck() error {
 	s.mu.Lock()
 	s.HealthCheckFuncInvoked = true
@@ -82,7 +82,7 @@ func TriggerFailingPoliciesAutomation(
 	}
 
 	// prepare the per-team configuration caches
-	getTeam := makeTeamConfigCache(ds, appConfig.Integrations)
+	getTeam := makeTeamConfigCache(ds, appConfig)
 
 	policySets, err := failingPoliciesSet.ListSets()
 	if err != nil {
@@ -153,7 +153,7 @@ func TriggerFailingPoliciesAutomation(
 	return nil
 }
 
-func makeTeamConfigCache(ds fleet.Datastore, globalIntgs fleet.Integrations) func(ctx context.Context, teamID uint) (FailingPolicyAutomationConfig, error) {
+func makeTeamConfigCache(ds fleet.Datastore, appConfig *fleet.AppConfig) func(ctx context.Context, teamID uint) (FailingPolicyAutomationConfig, error) {
 	teamCfgs := make(map[uint]FailingPolicyAutomationConfig)
 
 	return func(ctx context.Context, teamID uint) (FailingPolicyAutomationConfig, error) {
@@ -166,8 +166,9 @@ func makeTeamConfigCache(ds fleet.Datastore, globalIntgs fleet.Integrations) fun
 		if err != nil {
 			return cfg, ctxerr.Wrapf(ctx, err, "get team: %d", teamID)
 		}
+		team.ApplyInheritedSettings(appConfig)
 
-		intgs, err := team.Config.Integrations.MatchWithIntegrations(globalIntgs)
+		intgs, err := team.Config.Integrations.MatchWithIntegrations(appConfig.Integrations)
 		if err != nil {
 			return cfg, ctxerr.Wrap(ctx, err, "map team integrations to global integrations")
 		}
@@ -201,24 +202,5 @@ func makeTeamConfigCache(ds fleet.Datastore, globalIntgs fleet.Integrations) fun
 }
 
 func getActiveAutomation(webhook fleet.FailingPoliciesWebhookSettings, intgs fleet.Integrations) FailingPolicyAutomationType {
-	// only one automation (i.e. webhook or integration) can be enabled at a
-	// time, enforced when updating the appconfig or the team config.
-	if webhook.Enable {
-		return FailingPolicyWebhook
-	}
-
-	// check for jira integrations
-	for _, j := range intgs.Jira {
-		if j.EnableFailingPolicies {
-			return FailingPolicyJira
-		}
-	}
-
-	// check for zendesk integrations
-	for _, z := range intgs.Zendesk {
-		if z.EnableFailingPolicies {
-			return FailingPolicyZendesk
-		}
-	}
-	return ""
+	return FailingPolicyAutomationType(fleet.ActiveFailingPolicyAutomation(webhook, intgs))
 }
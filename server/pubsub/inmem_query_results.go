@@ -58,6 +58,18 @@ func (im *inmemQueryResults) ReadChannel(ctx context.Context, campaign fleet.Dis
 	return channel, nil
 }
 
+// ReadChannelFromCursor is the same as ReadChannel, the in-memory store does
+// not buffer results so there is nothing to replay.
+func (im *inmemQueryResults) ReadChannelFromCursor(ctx context.Context, campaign fleet.DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error) {
+	return im.ReadChannel(ctx, campaign)
+}
+
+// DetachCampaign always returns false, the in-memory store does not buffer
+// results so campaigns cannot be resumed.
+func (im *inmemQueryResults) DetachCampaign(ctx context.Context, campaignID uint) (bool, error) {
+	return false, nil
+}
+
 func (im *inmemQueryResults) HealthCheck() error {
 	return nil
 }
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/datastore/redis"
+	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/log"
 	redigo "github.com/gomodule/redigo/redis"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -214,3 +216,93 @@ func TestQueryResultsStore(t *testing.T) {
 		runTest(t, store)
 	})
 }
+
+func TestQueryResultsStoreResume(t *testing.T) {
+	runTest := func(t *testing.T, store *redisQueryResults) {
+		campaign := fleet.DistributedQueryCampaign{ID: 10}
+		newResult := func(hostID uint) fleet.DistributedQueryResult {
+			return fleet.DistributedQueryResult{
+				DistributedQueryCampaignID: campaign.ID,
+				Rows:                       []map[string]string{{"foo": "bar"}},
+				Host:                       fleet.ResultHostData{ID: hostID},
+			}
+		}
+		receive := func(ch <-chan interface{}) interface{} {
+			select {
+			case v := <-ch:
+				return v
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for channel value")
+			}
+			return nil
+		}
+
+		// not detached and no subscriber, the result is buffered but the
+		// write fails.
+		err := store.WriteResult(newResult(1))
+		require.Error(t, err)
+		castErr, ok := err.(Error)
+		require.True(t, ok)
+		require.True(t, castErr.NoSubscriber())
+
+		// detached, the result is buffered for a resuming client.
+		ok, err = store.DetachCampaign(context.Background(), campaign.ID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, store.WriteResult(newResult(2)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := store.ReadChannelFromCursor(ctx, campaign, 1)
+		require.NoError(t, err)
+
+		require.Equal(t, fleet.QueryResultsReplay{Cursor: 1, Count: 1}, receive(ch))
+		res := receive(ch).(fleet.DistributedQueryResult)
+		require.Equal(t, uint(2), res.Host.ID)
+		require.Equal(t, uint64(2), res.Cursor)
+
+		// new results are received with their cursor, until the buffer is full.
+		require.NoError(t, store.WriteResult(newResult(3)))
+		res = receive(ch).(fleet.DistributedQueryResult)
+		require.Equal(t, uint(3), res.Host.ID)
+		require.Equal(t, uint64(3), res.Cursor)
+
+		require.NoError(t, store.WriteResult(newResult(4)))
+		res = receive(ch).(fleet.DistributedQueryResult)
+		require.Equal(t, uint(4), res.Host.ID)
+		require.Zero(t, res.Cursor)
+		cancel()
+
+		// the campaign is not detached anymore after it was resumed.
+		time.Sleep(100 * time.Millisecond)
+		require.Error(t, store.WriteResult(newResult(5)))
+
+		// resuming from the start replays the whole (truncated) buffer.
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		ch, err = store.ReadChannelFromCursor(ctx, campaign, 0)
+		require.NoError(t, err)
+		require.Equal(t, fleet.QueryResultsReplay{Cursor: 0, Count: 3, Truncated: true}, receive(ch))
+		for i := 1; i <= 3; i++ {
+			res = receive(ch).(fleet.DistributedQueryResult)
+			require.Equal(t, uint(i), res.Host.ID)
+			require.Equal(t, uint64(i), res.Cursor)
+		}
+	}
+
+	t.Run("standalone", func(t *testing.T) {
+		pool := redistest.SetupRedis(t, "results_", false, false, false)
+		runTest(t, NewRedisQueryResults(pool, false, log.NewNopLogger(), WithResultsBuffer(3, time.Minute)))
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		pool := redistest.SetupRedis(t, "results_", true, true, false)
+		runTest(t, NewRedisQueryResults(pool, false, log.NewNopLogger(), WithResultsBuffer(3, time.Minute)))
+	})
+}
+
+func TestQueryResultsStoreDetachNoBuffer(t *testing.T) {
+	store := SetupRedisForTest(t, false, false)
+	ok, err := store.DetachCampaign(context.Background(), 11)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
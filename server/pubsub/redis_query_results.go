@@ -19,30 +19,72 @@ type redisQueryResults struct {
 	pool             fleet.RedisPool
 	duplicateResults bool
 	logger           log.Logger
+
+	// bufferSize is the maximum number of results buffered per campaign, 0
+	// disables the buffering of results.
+	bufferSize int
+	// bufferTTL is how long the results buffer of a campaign is kept after
+	// the last result was written to it, and how long a detached campaign can
+	// be resumed.
+	bufferTTL time.Duration
 }
 
 var _ fleet.QueryResultStore = &redisQueryResults{}
 
+// Option configures the Redis implementation of the QueryResultStore.
+type Option func(*redisQueryResults)
+
+// WithResultsBuffer enables buffering up to size results per campaign in
+// Redis, for ttl after the last result was written, so that clients can
+// resume reading the results of a campaign.
+func WithResultsBuffer(size int, ttl time.Duration) Option {
+	return func(r *redisQueryResults) {
+		r.bufferSize = size
+		r.bufferTTL = ttl
+	}
+}
+
 // NewRedisQueryResults creats a new Redis implementation of the
 // QueryResultStore interface using the provided Redis connection pool.
-func NewRedisQueryResults(pool fleet.RedisPool, duplicateResults bool, logger log.Logger) *redisQueryResults {
-	return &redisQueryResults{
+func NewRedisQueryResults(pool fleet.RedisPool, duplicateResults bool, logger log.Logger, opts ...Option) *redisQueryResults {
+	r := &redisQueryResults{
 		pool:             pool,
 		duplicateResults: duplicateResults,
 		logger:           logger,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func pubSubForID(id uint) string {
 	return fmt.Sprintf("results_%d", id)
 }
 
+func bufferForID(id uint) string {
+	return fmt.Sprintf("results_buffer_%d", id)
+}
+
+func detachedForID(id uint) string {
+	return fmt.Sprintf("results_detached_%d", id)
+}
+
 // Pool returns the redisc connection pool (used in tests).
 func (r *redisQueryResults) Pool() fleet.RedisPool {
 	return r.pool
 }
 
 func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) error {
+	if r.bufferSize > 0 {
+		cursor, err := r.bufferResult(result)
+		if err != nil {
+			// buffering is best-effort, the result is published anyway.
+			level.Error(r.logger).Log("msg", "buffer result", "campaignID", result.DistributedQueryCampaignID, "err", err)
+		}
+		result.Cursor = cursor
+	}
+
 	// pub-sub can publish and listen on any node in the cluster
 	conn := redis.ReadOnlyConn(r.pool, r.pool.Get())
 	defer conn.Close()
@@ -65,12 +107,147 @@ func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) err
 		return fmt.Errorf("PUBLISH failed to channel "+channelName+": %w", err)
 	}
 	if !hasSubs {
+		if r.bufferSize > 0 {
+			detached, err := r.isDetached(result.DistributedQueryCampaignID)
+			if err != nil {
+				return fmt.Errorf("check detached campaign: %w", err)
+			}
+			if detached {
+				// the result is buffered until a client resumes reading the
+				// campaign.
+				return nil
+			}
+		}
 		return noSubscriberError{channelName}
 	}
 
 	return nil
 }
 
+// bufferResult appends the result to the results buffer of its campaign and
+// returns its cursor, which is its position in the buffer. It returns 0 if
+// the buffer is full.
+func (r *redisQueryResults) bufferResult(result fleet.DistributedQueryResult) (uint64, error) {
+	key := bufferForID(result.DistributedQueryCampaignID)
+
+	jsonVal, err := json.Marshal(&result)
+	if err != nil {
+		return 0, fmt.Errorf("marshalling JSON for result: %w", err)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	if err := redis.BindConn(r.pool, conn, key); err != nil {
+		return 0, fmt.Errorf("bind redis connection: %w", err)
+	}
+
+	if err := conn.Send("RPUSH", key, jsonVal); err != nil {
+		return 0, fmt.Errorf("send RPUSH: %w", err)
+	}
+	if err := conn.Send("PEXPIRE", key, r.bufferTTL.Milliseconds()); err != nil {
+		return 0, fmt.Errorf("send PEXPIRE: %w", err)
+	}
+	if err := conn.Flush(); err != nil {
+		return 0, fmt.Errorf("flush pipeline: %w", err)
+	}
+	n, err := redigo.Uint64(conn.Receive())
+	if err != nil {
+		return 0, fmt.Errorf("push result to buffer: %w", err)
+	}
+	if _, err := conn.Receive(); err != nil {
+		return 0, fmt.Errorf("set buffer expiration: %w", err)
+	}
+
+	if n > uint64(r.bufferSize) {
+		// the buffer is full, the oldest results are kept so that the cursors
+		// remain the positions of the results in the buffer.
+		if _, err := conn.Do("LTRIM", key, 0, r.bufferSize-1); err != nil {
+			return 0, fmt.Errorf("trim buffer: %w", err)
+		}
+		return 0, nil
+	}
+	return n, nil
+}
+
+func (r *redisQueryResults) isDetached(campaignID uint) (bool, error) {
+	conn := redis.ConfigureDoer(r.pool, r.pool.Get())
+	defer conn.Close()
+
+	return redigo.Bool(conn.Do("EXISTS", detachedForID(campaignID)))
+}
+
+// DetachCampaign marks the campaign as detached for the buffer TTL, so that
+// its results are buffered until a client resumes reading them.
+func (r *redisQueryResults) DetachCampaign(ctx context.Context, campaignID uint) (bool, error) {
+	if r.bufferSize <= 0 {
+		return false, nil
+	}
+
+	conn := redis.ConfigureDoer(r.pool, r.pool.Get())
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", detachedForID(campaignID), 1, "PX", r.bufferTTL.Milliseconds()); err != nil {
+		return false, ctxerr.Wrap(ctx, err, "mark campaign as detached")
+	}
+	return true, nil
+}
+
+// replay sends the buffered results of the campaign with a cursor greater
+// than cursor on outChannel, preceded by a QueryResultsReplay value. It
+// returns the cursor of the last replayed result, and true if ctx is done.
+func (r *redisQueryResults) replay(ctx context.Context, campaignID uint, cursor uint64, outChannel chan<- interface{}) (uint64, bool) {
+	if r.bufferSize <= 0 {
+		return 0, writeOrDone(ctx, outChannel, fleet.QueryResultsReplay{Cursor: cursor, Truncated: cursor > 0})
+	}
+
+	conn := redis.ConfigureDoer(r.pool, r.pool.Get())
+	defer conn.Close()
+
+	// the campaign is read again, its results don't need to be kept for a
+	// client resuming it anymore.
+	if _, err := conn.Do("DEL", detachedForID(campaignID)); err != nil {
+		level.Error(r.logger).Log("msg", "delete detached campaign", "campaignID", campaignID, "err", err)
+	}
+
+	key := bufferForID(campaignID)
+	length, err := redigo.Uint64(conn.Do("LLEN", key))
+	if err != nil {
+		return 0, writeOrDone(ctx, outChannel, ctxerr.Wrap(ctx, err, "get results buffer length"))
+	}
+
+	info := fleet.QueryResultsReplay{Cursor: cursor, Truncated: length >= uint64(r.bufferSize)}
+	from := cursor
+	if from > length {
+		// the buffer expired since the client read the results, replay the
+		// new buffer from the start.
+		from = 0
+		info.Truncated = true
+	}
+	values, err := redigo.ByteSlices(conn.Do("LRANGE", key, from, -1))
+	if err != nil {
+		return 0, writeOrDone(ctx, outChannel, ctxerr.Wrap(ctx, err, "read results buffer"))
+	}
+
+	info.Count = len(values)
+	if writeOrDone(ctx, outChannel, info) {
+		return 0, true
+	}
+	for i, v := range values {
+		var res fleet.DistributedQueryResult
+		if err := json.Unmarshal(v, &res); err != nil {
+			if writeOrDone(ctx, outChannel, err) {
+				return 0, true
+			}
+			continue
+		}
+		res.Cursor = from + uint64(i) + 1
+		if writeOrDone(ctx, outChannel, res) {
+			return 0, true
+		}
+	}
+	return from + uint64(len(values)), false
+}
+
 // writeOrDone tries to write the item into the channel taking into account context.Done(). If context is done, returns
 // true, otherwise false
 func writeOrDone(ctx context.Context, ch chan<- interface{}, item interface{}) bool {
@@ -121,6 +298,18 @@ func receiveMessages(ctx context.Context, conn *redigo.PubSubConn, outChan chan<
 }
 
 func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error) {
+	return r.readChannel(ctx, query, nil)
+}
+
+func (r *redisQueryResults) ReadChannelFromCursor(ctx context.Context, query fleet.DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error) {
+	return r.readChannel(ctx, query, &cursor)
+}
+
+// readChannel subscribes to the results of the campaign. If replayFrom is not
+// nil, the buffered results after that cursor are replayed once the
+// subscription is confirmed, so that no result is missed between the replay
+// and the subscription.
+func (r *redisQueryResults) readChannel(ctx context.Context, query fleet.DistributedQueryCampaign, replayFrom *uint64) (<-chan interface{}, error) {
 	outChannel := make(chan interface{})
 	msgChannel := make(chan interface{})
 
@@ -151,6 +340,9 @@ func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.Distrib
 		defer wg.Done()
 		defer close(outChannel)
 
+		// the cursor of the last replayed result, results received on the
+		// subscription up to that cursor were already replayed.
+		var replayedUpTo uint64
 		for {
 			// Loop reading messages from conn.Receive() (via msgChannel) until the context is cancelled.
 			select {
@@ -167,6 +359,15 @@ func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.Distrib
 				}
 
 				switch msg := msg.(type) {
+				case redigo.Subscription:
+					if replayFrom != nil && msg.Kind == "subscribe" {
+						var done bool
+						replayedUpTo, done = r.replay(ctx, query.ID, *replayFrom, outChannel)
+						if done {
+							return
+						}
+						replayFrom = nil
+					}
 				case redigo.Message:
 					var res fleet.DistributedQueryResult
 					err := json.Unmarshal(msg.Data, &res)
@@ -175,6 +376,9 @@ func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.Distrib
 							return
 						}
 					}
+					if res.Cursor != 0 && res.Cursor <= replayedUpTo {
+						continue
+					}
 					if writeOrDone(ctx, outChannel, res) {
 						return
 					}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"html/template"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List admin notifications
+////////////////////////////////////////////////////////////////////////////////
+
+type listAdminNotificationsRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+	Unread      bool              `query:"unread,optional"`
+}
+
+type listAdminNotificationsResponse struct {
+	Meta          *fleet.PaginationMetadata  `json:"meta"`
+	Notifications []*fleet.AdminNotification `json:"notifications"`
+	UnreadCount   int                        `json:"unread_count"`
+	Err           error                      `json:"error,omitempty"`
+}
+
+func (r listAdminNotificationsResponse) error() error { return r.Err }
+
+func listAdminNotificationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listAdminNotificationsRequest)
+	notifications, meta, unread, err := svc.ListAdminNotifications(ctx, fleet.AdminNotificationListOptions{
+		ListOptions: req.ListOptions,
+		UnreadOnly:  req.Unread,
+	})
+	if err != nil {
+		return listAdminNotificationsResponse{Err: err}, nil
+	}
+	if notifications == nil {
+		notifications = []*fleet.AdminNotification{}
+	}
+	return listAdminNotificationsResponse{Meta: meta, Notifications: notifications, UnreadCount: unread}, nil
+}
+
+func (svc *Service) ListAdminNotifications(ctx context.Context, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, int, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AdminNotification{}, fleet.ActionRead); err != nil {
+		return nil, nil, 0, err
+	}
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, nil, 0, fleet.ErrNoContext
+	}
+
+	notifications, meta, err := svc.ds.ListAdminNotifications(ctx, vc.UserID(), opts)
+	if err != nil {
+		return nil, nil, 0, ctxerr.Wrap(ctx, err, "list admin notifications")
+	}
+	unread, err := svc.ds.CountUnreadAdminNotifications(ctx, vc.UserID())
+	if err != nil {
+		return nil, nil, 0, ctxerr.Wrap(ctx, err, "count unread admin notifications")
+	}
+	return notifications, meta, unread, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Mark admin notifications as read
+////////////////////////////////////////////////////////////////////////////////
+
+type markAdminNotificationsReadRequest struct {
+	// NotificationIDs are the notifications to mark as read, all of them if
+	// empty.
+	NotificationIDs []uint `json:"notification_ids"`
+}
+
+type markAdminNotificationsReadResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r markAdminNotificationsReadResponse) error() error { return r.Err }
+
+func markAdminNotificationsReadEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*markAdminNotificationsReadRequest)
+	if err := svc.MarkAdminNotificationsRead(ctx, req.NotificationIDs); err != nil {
+		return markAdminNotificationsReadResponse{Err: err}, nil
+	}
+	return markAdminNotificationsReadResponse{}, nil
+}
+
+func (svc *Service) MarkAdminNotificationsRead(ctx context.Context, ids []uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AdminNotification{}, fleet.ActionRead); err != nil {
+		return err
+	}
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	if err := svc.ds.MarkAdminNotificationsRead(ctx, vc.UserID(), ids); err != nil {
+		return ctxerr.Wrap(ctx, err, "mark admin notifications read")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete (dismiss) admin notification
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteAdminNotificationRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteAdminNotificationResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteAdminNotificationResponse) error() error { return r.Err }
+
+func deleteAdminNotificationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteAdminNotificationRequest)
+	if err := svc.DeleteAdminNotification(ctx, req.ID); err != nil {
+		return deleteAdminNotificationResponse{Err: err}, nil
+	}
+	return deleteAdminNotificationResponse{}, nil
+}
+
+func (svc *Service) DeleteAdminNotification(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AdminNotification{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if err := svc.ds.DeleteAdminNotification(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete admin notification")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Admin notifications cron
+////////////////////////////////////////////////////////////////////////////////
+
+// CheckAdminNotificationConditions raises the admin notifications for the
+// conditions of the server that require the attention of the admins, e.g. an
+// expiring APNs certificate.
+func CheckAdminNotificationConditions(ctx context.Context, ds fleet.Datastore, mdmConfig *config.MDMConfig, now time.Time) error {
+	var notifications []*fleet.AdminNotification
+
+	if mdmConfig.IsAppleAPNsSet() {
+		cert, _, _, err := mdmConfig.AppleAPNs()
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get APNs certificate")
+		}
+		if cert.Leaf != nil {
+			if n := fleet.NewAPNsCertExpiringNotification(cert.Leaf.NotAfter, now); n != nil {
+				notifications = append(notifications, n)
+			}
+		}
+	}
+
+	if mdmConfig.IsAppleBMSet() {
+		tok, err := mdmConfig.AppleBM()
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get ABM token")
+		}
+		if n := fleet.NewABMTokenExpiringNotification(tok.AccessTokenExpiry, now); n != nil {
+			notifications = append(notifications, n)
+		}
+
+		appConfig, err := ds.AppConfig(ctx)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get app config")
+		}
+		if appConfig.MDM.AppleBMTermsExpired {
+			notifications = append(notifications, fleet.NewABMTermsExpiredNotification())
+		}
+	}
+
+	for _, n := range notifications {
+		if err := ds.NewAdminNotification(ctx, n); err != nil {
+			return ctxerr.Wrap(ctx, err, "create admin notification")
+		}
+	}
+	return nil
+}
+
+// SendAdminNotificationsDigests emails to each global admin the admin
+// notifications raised since the previous digest that they did not read, if
+// the daily email digest is enabled and SMTP is configured.
+func SendAdminNotificationsDigests(ctx context.Context, ds fleet.Datastore, mailService fleet.MailService, urlPrefix string, now time.Time, logger kitlog.Logger) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if !appConfig.AdminNotifications.DailyEmailDigest {
+		return nil
+	}
+	if appConfig.SMTPSettings == nil || !appConfig.SMTPSettings.SMTPConfigured {
+		level.Info(logger).Log("msg", "skipping admin notifications digest, SMTP is not configured")
+		return nil
+	}
+
+	users, err := ds.ListUsers(ctx, fleet.UserListOptions{})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list users")
+	}
+
+	since := now.Add(-fleet.AdminNotificationDigestInterval)
+	for _, u := range users {
+		if u.GlobalRole == nil || *u.GlobalRole != fleet.RoleAdmin || u.APIOnly {
+			continue
+		}
+
+		notifications, _, err := ds.ListAdminNotifications(ctx, u.ID, fleet.AdminNotificationListOptions{
+			UnreadOnly:   true,
+			UpdatedSince: &since,
+		})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "list admin notifications")
+		}
+		if len(notifications) == 0 {
+			continue
+		}
+
+		if err := mailService.SendEmail(fleet.Email{
+			Subject:      "Fleet notifications digest",
+			To:           []string{u.Email},
+			ServerURL:    appConfig.ServerSettings.ServerURL,
+			SMTPSettings: *appConfig.SMTPSettings,
+			Mailer: &mail.AdminNotificationsDigestMailer{
+				BaseURL:       template.URL(appConfig.ServerSettings.ServerURL + urlPrefix),
+				AssetURL:      getAssetURL(),
+				Notifications: notifications,
+			},
+		}); err != nil {
+			// keep sending the digest to the other admins
+			level.Error(logger).Log("msg", "send admin notifications digest", "user_id", u.ID, "err", err)
+			ctxerr.Handle(ctx, ctxerr.Wrap(ctx, err, "send admin notifications digest"))
+		}
+	}
+	return nil
+}
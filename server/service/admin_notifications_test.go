@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminNotificationsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.ListAdminNotificationsFunc = func(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error) {
+		return nil, &fleet.PaginationMetadata{}, nil
+	}
+	ds.CountUnreadAdminNotificationsFunc = func(ctx context.Context, userID uint) (int, error) {
+		return 0, nil
+	}
+	ds.MarkAdminNotificationsReadFunc = func(ctx context.Context, userID uint, notificationIDs []uint) error {
+		return nil
+	}
+	ds.DeleteAdminNotificationFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global maintainer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleMaintainer)}, true},
+		{"global observer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team admin", &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, _, _, err := svc.ListAdminNotifications(ctx, fleet.AdminNotificationListOptions{})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			err = svc.MarkAdminNotificationsRead(ctx, nil)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			err = svc.DeleteAdminNotification(ctx, 1)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+}
+
+func TestListAdminNotifications(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 42, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ds.ListAdminNotificationsFunc = func(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error) {
+		require.EqualValues(t, 42, userID)
+		require.True(t, opts.UnreadOnly)
+		return []*fleet.AdminNotification{{ID: 1}}, &fleet.PaginationMetadata{HasNextResults: true}, nil
+	}
+	ds.CountUnreadAdminNotificationsFunc = func(ctx context.Context, userID uint) (int, error) {
+		require.EqualValues(t, 42, userID)
+		return 3, nil
+	}
+	ds.MarkAdminNotificationsReadFunc = func(ctx context.Context, userID uint, notificationIDs []uint) error {
+		require.EqualValues(t, 42, userID)
+		require.Equal(t, []uint{1, 2}, notificationIDs)
+		return nil
+	}
+
+	notifications, meta, unread, err := svc.ListAdminNotifications(ctx, fleet.AdminNotificationListOptions{UnreadOnly: true})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	require.True(t, meta.HasNextResults)
+	require.Equal(t, 3, unread)
+
+	require.NoError(t, svc.MarkAdminNotificationsRead(ctx, []uint{1, 2}))
+	require.True(t, ds.MarkAdminNotificationsReadFuncInvoked)
+}
+
+func TestCheckAdminNotificationConditions(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+
+	var created []*fleet.AdminNotification
+	ds.NewAdminNotificationFunc = func(ctx context.Context, notification *fleet.AdminNotification) error {
+		created = append(created, notification)
+		return nil
+	}
+	var termsExpired bool
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		ac := &fleet.AppConfig{}
+		ac.MDM.AppleBMTermsExpired = termsExpired
+		return ac, nil
+	}
+
+	// MDM is not configured
+	cfg := config.TestConfig()
+	require.NoError(t, CheckAdminNotificationConditions(ctx, ds, &cfg.MDM, time.Now()))
+	require.Empty(t, created)
+
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	tok, err := cfg.MDM.AppleBM()
+	require.NoError(t, err)
+
+	// the APNs certificate expires in a year
+	now := time.Now()
+	require.NoError(t, CheckAdminNotificationConditions(ctx, ds, &cfg.MDM, now))
+	for _, n := range created {
+		require.NotEqual(t, fleet.AdminNotificationAPNsCertExpiring, n.Type)
+	}
+	if fleet.NewABMTokenExpiringNotification(tok.AccessTokenExpiry, now) != nil {
+		require.Len(t, created, 1)
+		require.Equal(t, fleet.AdminNotificationABMTokenInvalid, created[0].Type)
+	} else {
+		require.Empty(t, created)
+	}
+
+	// later, the APNs certificate and the ABM token have expired
+	later := now.Add(365 * 24 * time.Hour)
+	if tok.AccessTokenExpiry.After(later) {
+		later = tok.AccessTokenExpiry
+	}
+	created = nil
+	termsExpired = true
+	require.NoError(t, CheckAdminNotificationConditions(ctx, ds, &cfg.MDM, later.Add(24*time.Hour)))
+	require.Len(t, created, 3)
+	require.Equal(t, fleet.AdminNotificationAPNsCertExpiring, created[0].Type)
+	require.Equal(t, fleet.AdminNotificationSeverityError, created[0].Severity)
+	require.Equal(t, fleet.AdminNotificationABMTokenInvalid, created[1].Type)
+	require.Equal(t, fleet.NewABMTermsExpiredNotification().Key, created[2].Key)
+}
+
+type recordingMailService struct {
+	sent []fleet.Email
+}
+
+func (m *recordingMailService) SendEmail(e fleet.Email) error {
+	m.sent = append(m.sent, e)
+	return nil
+}
+
+func TestSendAdminNotificationsDigests(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	mailer := &recordingMailService{}
+	now := time.Now()
+
+	appConfig := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"},
+		SMTPSettings:   &fleet.SMTPSettings{SMTPConfigured: true},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	ds.ListUsersFunc = func(ctx context.Context, opt fleet.UserListOptions) ([]*fleet.User, error) {
+		return []*fleet.User{
+			{ID: 1, Email: "admin1@example.com", GlobalRole: ptr.String(fleet.RoleAdmin)},
+			{ID: 2, Email: "admin2@example.com", GlobalRole: ptr.String(fleet.RoleAdmin)},
+			{ID: 3, Email: "maintainer@example.com", GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			{ID: 4, Email: "api@example.com", GlobalRole: ptr.String(fleet.RoleAdmin), APIOnly: true},
+		}, nil
+	}
+	ds.ListAdminNotificationsFunc = func(ctx context.Context, userID uint, opts fleet.AdminNotificationListOptions) ([]*fleet.AdminNotification, *fleet.PaginationMetadata, error) {
+		require.True(t, opts.UnreadOnly)
+		require.NotNil(t, opts.UpdatedSince)
+		require.WithinDuration(t, now.Add(-fleet.AdminNotificationDigestInterval), *opts.UpdatedSince, time.Second)
+		if userID == 2 {
+			// admin2 read everything
+			return nil, &fleet.PaginationMetadata{}, nil
+		}
+		return []*fleet.AdminNotification{{ID: 1, Title: "title"}}, &fleet.PaginationMetadata{}, nil
+	}
+
+	// the digest is disabled
+	require.NoError(t, SendAdminNotificationsDigests(ctx, ds, mailer, "", now, kitlog.NewNopLogger()))
+	require.False(t, ds.ListUsersFuncInvoked)
+	require.Empty(t, mailer.sent)
+
+	// SMTP is not configured
+	appConfig.AdminNotifications.DailyEmailDigest = true
+	appConfig.SMTPSettings.SMTPConfigured = false
+	require.NoError(t, SendAdminNotificationsDigests(ctx, ds, mailer, "", now, kitlog.NewNopLogger()))
+	require.False(t, ds.ListUsersFuncInvoked)
+	require.Empty(t, mailer.sent)
+
+	appConfig.SMTPSettings.SMTPConfigured = true
+	require.NoError(t, SendAdminNotificationsDigests(ctx, ds, mailer, "/prefix", now, kitlog.NewNopLogger()))
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, []string{"admin1@example.com"}, mailer.sent[0].To)
+	digest, ok := mailer.sent[0].Mailer.(*mail.AdminNotificationsDigestMailer)
+	require.True(t, ok)
+	require.EqualValues(t, "https://fleet.example.com/prefix", digest.BaseURL)
+	require.Len(t, digest.Notifications, 1)
+}
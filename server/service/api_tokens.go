@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create API token
+////////////////////////////////////////////////////////////////////////////////
+
+type createAPITokenRequest struct {
+	fleet.APITokenPayload
+}
+
+type createAPITokenResponse struct {
+	APIToken *fleet.APIToken `json:"api_token,omitempty"`
+	Err      error           `json:"error,omitempty"`
+}
+
+func (r createAPITokenResponse) error() error { return r.Err }
+
+func createAPITokenEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*createAPITokenRequest)
+	tok, err := svc.NewAPIToken(ctx, req.APITokenPayload)
+	if err != nil {
+		return createAPITokenResponse{Err: err}, nil
+	}
+	return createAPITokenResponse{APIToken: tok}, nil
+}
+
+func (svc *Service) NewAPIToken(ctx context.Context, payload fleet.APITokenPayload) (*fleet.APIToken, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List API tokens
+////////////////////////////////////////////////////////////////////////////////
+
+type listAPITokensRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type listAPITokensResponse struct {
+	APITokens []*fleet.APIToken `json:"api_tokens"`
+	Err       error             `json:"error,omitempty"`
+}
+
+func (r listAPITokensResponse) error() error { return r.Err }
+
+func listAPITokensEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listAPITokensRequest)
+	toks, err := svc.ListAPITokens(ctx, req.TeamID)
+	if err != nil {
+		return listAPITokensResponse{Err: err}, nil
+	}
+	if toks == nil {
+		toks = []*fleet.APIToken{} // return empty json array instead of json null
+	}
+	return listAPITokensResponse{APITokens: toks}, nil
+}
+
+func (svc *Service) ListAPITokens(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete API token
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteAPITokenRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteAPITokenResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteAPITokenResponse) error() error { return r.Err }
+
+func deleteAPITokenEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteAPITokenRequest)
+	if err := svc.DeleteAPIToken(ctx, req.ID); err != nil {
+		return deleteAPITokenResponse{Err: err}, nil
+	}
+	return deleteAPITokenResponse{}, nil
+}
+
+func (svc *Service) DeleteAPIToken(ctx context.Context, id uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Authenticate API token
+////////////////////////////////////////////////////////////////////////////////
+
+// apiTokenMarkUsedInterval is the minimum interval between two updates of
+// the last time an API token was used.
+const apiTokenMarkUsedInterval = time.Minute
+
+func (svc *Service) AuthenticateAPIToken(ctx context.Context, token string) (*fleet.APIToken, *fleet.User, error) {
+	// skipauth: this is the authentication step, the token is the credential.
+	svc.authz.SkipAuthorization(ctx)
+
+	if token == "" {
+		return nil, nil, fleet.NewAuthRequiredError("missing API token")
+	}
+
+	tok, err := svc.ds.APITokenByHash(ctx, fleet.HashAPITokenSecret(token))
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "find api token")
+	}
+
+	creator, err := svc.ds.UserByID(ctx, tok.CreatedByUserID)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "find api token creator")
+	}
+	// the token can never do more than its creator is currently allowed to do
+	// on the team, so that it doesn't keep working after they are demoted.
+	role := tok.RoleFor(creator)
+	if role == "" {
+		return nil, nil, fleet.NewPermissionError("The user that created this API token no longer has access to its team.")
+	}
+
+	// last_used_at is only updated once per interval to avoid a write on every
+	// request.
+	now := svc.clock.Now()
+	if tok.LastUsedAt == nil || now.Sub(*tok.LastUsedAt) >= apiTokenMarkUsedInterval {
+		if err := svc.ds.MarkAPITokenUsed(ctx, tok.ID, now); err != nil {
+			return nil, nil, ctxerr.Wrap(ctx, err, "mark api token used")
+		}
+	}
+
+	// The token acts on behalf of the user that created it (so that activities
+	// are attributed to that user) but with only the team role granted by its
+	// scope, never the creator's own roles.
+	user := &fleet.User{
+		ID:      creator.ID,
+		Name:    creator.Name,
+		Email:   creator.Email,
+		APIOnly: true,
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: tok.TeamID}, Role: role},
+		},
+	}
+	return tok, user, nil
+}
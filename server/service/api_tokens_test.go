@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokensFreeLicense(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.NewAPIToken(ctx, fleet.APITokenPayload{Name: "tok", TeamID: 1, Scope: fleet.APITokenScopeScripts})
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+	_, err = svc.ListAPITokens(ctx, nil)
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+	err = svc.DeleteAPIToken(ctx, 1)
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+}
+
+func TestAPITokensAuth(t *testing.T) {
+	ds := new(mock.Store)
+	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	ds.NewAPITokenFunc = func(ctx context.Context, token *fleet.APIToken, tokenHash string) (*fleet.APIToken, error) {
+		require.Len(t, tokenHash, 64)
+		tok := *token
+		tok.ID = 1
+		return &tok, nil
+	}
+	ds.ListAPITokensFunc = func(ctx context.Context, teamID *uint) ([]*fleet.APIToken, error) {
+		return nil, nil
+	}
+	ds.APITokenFunc = func(ctx context.Context, id uint) (*fleet.APIToken, error) {
+		return &fleet.APIToken{ID: id, TeamID: 1, Name: "tok", Scope: fleet.APITokenScopeMDM}, nil
+	}
+	ds.DeleteAPITokenFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name             string
+		user             *fleet.User
+		shouldFailTeam   bool
+		shouldFailGlobal bool
+	}{
+		{
+			"global admin",
+			&fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			true,
+			true,
+		},
+		{
+			"team admin, belongs to team",
+			&fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			false,
+			true,
+		},
+		{
+			"team admin, DOES NOT belong to team",
+			&fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			true,
+			true,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			tok, err := svc.NewAPIToken(ctx, fleet.APITokenPayload{Name: "tok", TeamID: 1, Scope: fleet.APITokenScopeMDM})
+			checkAuthErr(t, tt.shouldFailTeam, err)
+			if !tt.shouldFailTeam {
+				require.NotEmpty(t, tok.Token)
+				require.Equal(t, tt.user.ID, tok.CreatedByUserID)
+			}
+
+			_, err = svc.ListAPITokens(ctx, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeam, err)
+
+			_, err = svc.ListAPITokens(ctx, nil)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+
+			err = svc.DeleteAPIToken(ctx, 1)
+			checkAuthErr(t, tt.shouldFailTeam, err)
+		})
+	}
+
+	t.Run("invalid payload", func(t *testing.T) {
+		ctx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+		_, err := svc.NewAPIToken(ctx, fleet.APITokenPayload{Name: "tok", TeamID: 1, Scope: "admin"})
+		var iae *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &iae)
+	})
+}
+
+func TestAPITokenViewer(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock(time.Now())
+	svc, ctx := newTestServiceWithClock(t, ds, nil, nil, mockClock)
+
+	secret := "s3cr3t"
+	var lastUsedAt *time.Time
+	var markedUsed int
+	ds.APITokenByHashFunc = func(ctx context.Context, tokenHash string) (*fleet.APIToken, error) {
+		if tokenHash != fleet.HashAPITokenSecret(secret) {
+			return nil, newNotFoundError()
+		}
+		return &fleet.APIToken{ID: 1, TeamID: 3, Scope: fleet.APITokenScopeScripts, CreatedByUserID: 42, LastUsedAt: lastUsedAt}, nil
+	}
+	creator := &fleet.User{ID: 42, Email: "admin@example.com", GlobalRole: ptr.String(fleet.RoleAdmin)}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return creator, nil
+	}
+	ds.MarkAPITokenUsedFunc = func(ctx context.Context, id uint, usedAt time.Time) error {
+		markedUsed++
+		lastUsedAt = &usedAt
+		return nil
+	}
+
+	requestCtx := func(method, path string) context.Context {
+		ctx := context.WithValue(ctx, kithttp.ContextKeyRequestMethod, method)
+		return context.WithValue(ctx, kithttp.ContextKeyRequestPath, path)
+	}
+
+	// unknown token
+	_, err := apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/scripts"), "nosuchtoken", svc)
+	require.True(t, fleet.IsNotFound(err))
+	require.Zero(t, markedUsed)
+
+	// allowed by the scope, the viewer only has the token's team role
+	v, err := apiTokenViewer(requestCtx(http.MethodPost, "/api/latest/fleet/scripts/run"), secret, svc)
+	require.NoError(t, err)
+	require.Equal(t, 1, markedUsed)
+	require.Equal(t, uint(42), v.User.ID)
+	require.True(t, v.User.APIOnly)
+	require.Nil(t, v.User.GlobalRole)
+	require.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleMaintainer}}, v.User.Teams)
+
+	// the last use is only recorded once per interval
+	mockClock.AddTime(apiTokenMarkUsedInterval / 2)
+	_, err = apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/scripts"), secret, svc)
+	require.NoError(t, err)
+	require.Equal(t, 1, markedUsed)
+	mockClock.AddTime(apiTokenMarkUsedInterval / 2)
+	_, err = apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/scripts"), secret, svc)
+	require.NoError(t, err)
+	require.Equal(t, 2, markedUsed)
+
+	// not allowed by the scope
+	_, err = apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/users"), secret, svc)
+	var pe *fleet.PermissionError
+	require.ErrorAs(t, err, &pe)
+
+	// the creator was demoted to observer of the team, the token is capped
+	creator = &fleet.User{ID: 42, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleObserver}}}
+	v, err = apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/scripts"), secret, svc)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleObserver}}, v.User.Teams)
+
+	// the creator was removed from the team, the token doesn't work anymore
+	creator = &fleet.User{ID: 42, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 4}, Role: fleet.RoleAdmin}}}
+	_, err = apiTokenViewer(requestCtx(http.MethodGet, "/api/latest/fleet/scripts"), secret, svc)
+	require.ErrorAs(t, err, &pe)
+	require.ErrorContains(t, err, "no longer has access to its team")
+}
@@ -127,13 +127,17 @@ func getAppConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Se
 	}
 
 	// Only the Global Admin should be able to see see SMTP, SSO and osquery agent settings.
+	// The SSO and agent settings are shared by all tenants, so they are not
+	// shown to the users of a tenant.
 	var smtpSettings *fleet.SMTPSettings
 	var ssoSettings *fleet.SSOSettings
 	var agentOptions *json.RawMessage
 	if vc.User.GlobalRole != nil && *vc.User.GlobalRole == fleet.RoleAdmin {
 		smtpSettings = appConfig.SMTPSettings
-		ssoSettings = appConfig.SSOSettings
-		agentOptions = appConfig.AgentOptions
+		if vc.User.TenantID == nil {
+			ssoSettings = appConfig.SSOSettings
+			agentOptions = appConfig.AgentOptions
+		}
 	}
 
 	transparencyURL := fleet.DefaultTransparencyURL
@@ -156,6 +160,19 @@ func getAppConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Se
 			VulnerabilitySettings:  appConfig.VulnerabilitySettings,
 			HostExpirySettings:     appConfig.HostExpirySettings,
 			ActivityExpirySettings: appConfig.ActivityExpirySettings,
+			SessionSettings:        appConfig.SessionSettings,
+			MFASettings:            appConfig.MFASettings,
+			EnrollmentApproval:     appConfig.EnrollmentApproval,
+			DuplicateHosts:         appConfig.DuplicateHosts,
+			FileRetrieval:          appConfig.FileRetrieval,
+			LockWipeSettings:       appConfig.LockWipeSettings,
+			RemoteQueries:          appConfig.RemoteQueries,
+			VulnerabilitySLA:       appConfig.VulnerabilitySLA,
+			HostCustomFields:       appConfig.HostCustomFields,
+			OrbitFeatureFlags:      appConfig.OrbitFeatureFlags,
+			AdminNotifications:     appConfig.AdminNotifications,
+			GitOpsMode:             appConfig.GitOpsMode,
+			Localization:           appConfig.Localization,
 
 			SMTPSettings: smtpSettings,
 			SSOSettings:  ssoSettings,
@@ -191,7 +208,7 @@ func (svc *Service) AppConfigObfuscated(ctx context.Context) (*fleet.AppConfig,
 		}
 	}
 
-	ac, err := svc.ds.AppConfig(ctx)
+	ac, err := svc.appConfigForUser(ctx, authz.UserFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +218,15 @@ func (svc *Service) AppConfigObfuscated(ctx context.Context) (*fleet.AppConfig,
 	return ac, nil
 }
 
+// appConfigForUser returns the app config that applies to the user, with the
+// overrides of the user's tenant, if any.
+func (svc *Service) appConfigForUser(ctx context.Context, user *fleet.User) (*fleet.AppConfig, error) {
+	if user == nil || user.TenantID == nil {
+		return svc.ds.AppConfig(ctx)
+	}
+	return svc.ds.TenantAppConfig(ctx, *user.TenantID)
+}
+
 // //////////////////////////////////////////////////////////////////////////////
 // Modify AppConfig
 // //////////////////////////////////////////////////////////////////////////////
@@ -269,6 +295,14 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 	// We do not use svc.License(ctx) to allow roles (like GitOps) write but not read access to AppConfig.
 	license, _ := license.FromContext(ctx)
 
+	// when GitOps mode is enabled, the users not allowed to apply GitOps can
+	// only change the GitOps mode settings (e.g. to disable it).
+	if appConfig.GitOpsMode.GitOpsModeEnabled && !gitOpsModeSettingsOnly(p) {
+		if vc, ok := viewer.FromContext(ctx); !ok || !vc.User.CanApplyInGitOpsMode() {
+			return nil, ctxerr.Wrap(ctx, &fleet.GitOpsModeError{RepositoryURL: appConfig.GitOpsMode.RepositoryURL})
+		}
+	}
+
 	var oldSMTPSettings fleet.SMTPSettings
 	if appConfig.SMTPSettings != nil {
 		oldSMTPSettings = *appConfig.SMTPSettings
@@ -381,6 +415,37 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 		invalid.Append("activity_expiry_settings.activity_expiry_window", "must be greater than 0")
 	}
 
+	if appConfig.SessionSettings.MaxSessionLifetime < 0 {
+		invalid.Append("session_settings.max_session_lifetime", "must be greater than or equal to 0")
+	}
+	if appConfig.SessionSettings.IdleTimeout < 0 {
+		invalid.Append("session_settings.idle_timeout", "must be greater than or equal to 0")
+	}
+
+	if err := appConfig.FileRetrieval.Validate(); err != nil {
+		invalid.Append("file_retrieval.allowed_paths", err.Error())
+	}
+
+	fleet.ValidateHostCustomFieldDefinitions(appConfig.HostCustomFields, invalid)
+	fleet.ValidateOrbitFeatureFlags(appConfig.OrbitFeatureFlags, invalid)
+
+	appConfig.VulnerabilitySLA.Validate("vulnerability_sla", invalid)
+
+	if err := appConfig.Localization.Validate(); err != nil {
+		invalid.Append("localization.locale", err.Error())
+	}
+
+	appConfig.OrgInfo.ValidateBranding("org_info", invalid)
+
+	if appConfig.GitOpsMode.GitOpsModeEnabled {
+		if !oldAppConfig.GitOpsMode.GitOpsModeEnabled && !license.IsPremium() {
+			invalid.Append("gitops.gitops_mode_enabled", ErrMissingLicense.Error())
+		}
+		if appConfig.GitOpsMode.RepositoryURL == "" {
+			invalid.Append("gitops.repository_url", "must be set to enable GitOps mode")
+		}
+	}
+
 	if appConfig.OrgInfo.ContactURL == "" {
 		appConfig.OrgInfo.ContactURL = fleet.DefaultOrgInfoContactURL
 	}
@@ -405,9 +470,18 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 	}
 
 	fleet.ValidateGoogleCalendarIntegrations(appConfig.Integrations.GoogleCalendar, invalid)
+	if newAppConfig.Integrations.Warranty != nil {
+		// replace the integrations as a whole, so that omitted fields are not
+		// kept from the stored ones.
+		appConfig.Integrations.Warranty = newAppConfig.Integrations.Warranty
+		fleet.ValidateWarrantyIntegrations(oldAppConfig.Integrations.Warranty, appConfig.Integrations.Warranty, invalid)
+	}
 	fleet.ValidateEnabledVulnerabilitiesIntegrations(appConfig.WebhookSettings.VulnerabilitiesWebhook, appConfig.Integrations, invalid)
 	fleet.ValidateEnabledFailingPoliciesIntegrations(appConfig.WebhookSettings.FailingPoliciesWebhook, appConfig.Integrations, invalid)
 	fleet.ValidateEnabledHostStatusIntegrations(appConfig.WebhookSettings.HostStatusWebhook, invalid)
+	fleet.ValidateEnabledCertificateExpiryIntegrations(appConfig.WebhookSettings.CertificateExpiryWebhook, invalid)
+	fleet.ValidateEnabledTamperEventsIntegrations(appConfig.WebhookSettings.TamperEventsWebhook, invalid)
+	fleet.ValidateEnabledMDMEnrollmentIntegrations(appConfig.WebhookSettings.MDMEnrollmentWebhook, invalid)
 	if err := svc.validateMDM(ctx, license, &oldAppConfig.MDM, &appConfig.MDM, invalid); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "validating MDM config")
 	}
@@ -501,6 +575,10 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 	if newAppConfig.Integrations.GoogleCalendar == nil {
 		appConfig.Integrations.GoogleCalendar = oldAppConfig.Integrations.GoogleCalendar
 	}
+	// Same for warranty, null keeps the existing integrations.
+	if newAppConfig.Integrations.Warranty == nil {
+		appConfig.Integrations.Warranty = oldAppConfig.Integrations.Warranty
+	}
 
 	if !license.IsPremium() {
 		// reset transparency url to empty for downgraded licenses
@@ -510,6 +588,19 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 	if err := svc.ds.SaveAppConfig(ctx, appConfig); err != nil {
 		return nil, err
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, nil, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record config version")
+	}
+
+	if customFieldsRemoved(oldAppConfig.HostCustomFields, appConfig.HostCustomFields) {
+		names := make([]string, 0, len(appConfig.HostCustomFields))
+		for _, def := range appConfig.HostCustomFields {
+			names = append(names, def.Name)
+		}
+		if err := svc.ds.DeleteHostCustomFieldValuesNotIn(ctx, names); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "delete values of removed host custom fields")
+		}
+	}
 
 	if oldAppConfig.MDM.MacOSSetup.MacOSSetupAssistant.Value != appConfig.MDM.MacOSSetup.MacOSSetupAssistant.Value &&
 		appConfig.MDM.MacOSSetup.MacOSSetupAssistant.Value == "" {
@@ -692,6 +783,21 @@ func (svc *Service) HasCustomSetupAssistantConfigurationWebURL(ctx context.Conte
 	return ok, nil
 }
 
+// gitOpsModeSettingsOnly returns true if the AppConfig patch p only contains
+// the GitOps mode settings.
+func gitOpsModeSettingsOnly(p []byte) bool {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(p, &keys); err != nil {
+		return false
+	}
+	for k := range keys {
+		if k != "gitops" {
+			return false
+		}
+	}
+	return true
+}
+
 func (svc *Service) validateMDM(
 	ctx context.Context,
 	license *fleet.LicenseInfo,
@@ -715,6 +821,10 @@ func (svc *Service) validateMDM(
 		invalid.Append("macos_setup.enable_end_user_authentication", ErrMissingLicense.Error())
 	}
 
+	if err := mdm.MacOSSettings.EraseDevice.Validate(); err != nil {
+		invalid.Append("macos_settings.erase_device", err.Error())
+	}
+
 	// we want to use `oldMdm` here as this boolean is set by the fleet
 	// server at startup and can't be modified by the user
 	if !oldMdm.EnabledAndConfigured {
@@ -899,6 +1009,17 @@ func validateSSOProviderSettings(incoming, existing fleet.SSOProviderSettings, i
 	}
 }
 
+// customFieldsRemoved returns true if any of the old host custom fields is not
+// defined anymore in the new ones.
+func customFieldsRemoved(oldDefs, newDefs []fleet.HostCustomFieldDefinition) bool {
+	for _, def := range oldDefs {
+		if _, ok := fleet.HostCustomFieldDefinitionByName(newDefs, def.Name); !ok {
+			return true
+		}
+	}
+	return false
+}
+
 func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *fleet.InvalidArgumentError, license *fleet.LicenseInfo) {
 	if p.SSOSettings != nil && p.SSOSettings.EnableSSO {
 
@@ -957,6 +1078,10 @@ func (svc *Service) ApplyEnrollSecretSpec(ctx context.Context, spec *fleet.Enrol
 		return ctxerr.New(ctx, "enroll secret cannot be changed when fleet_packaging.global_enroll_secret is set")
 	}
 
+	if err := fleet.ValidateEnrollSecretHostRules(ctx, svc.ds, spec.Secrets); err != nil {
+		return ctxerr.Wrap(ctx, err, "validate enroll secrets")
+	}
+
 	return svc.ds.ApplyEnrollSecrets(ctx, nil, spec.Secrets)
 }
 
@@ -49,6 +49,9 @@ func TestAppConfigAuth(t *testing.T) {
 	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	testCases := []struct {
 		name            string
@@ -139,6 +142,45 @@ func TestAppConfigAuth(t *testing.T) {
 	}
 }
 
+func TestAppConfigTenant(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "Acme"}}, nil
+	}
+	ds.TenantAppConfigFunc = func(ctx context.Context, tenantID uint) (*fleet.AppConfig, error) {
+		require.Equal(t, uint(1), tenantID)
+		return &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "Globex"}}, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+
+	// the users of a tenant get the config of their tenant, but cannot modify
+	// the global config, whatever their role
+	tenantCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{
+		ID:            1,
+		GlobalRole:    ptr.String(fleet.RoleAdmin),
+		TenantID:      ptr.Uint(1),
+		TenantTeamIDs: []uint{1},
+	}})
+	ac, err := svc.AppConfigObfuscated(tenantCtx)
+	require.NoError(t, err)
+	require.Equal(t, "Globex", ac.OrgInfo.OrgName)
+
+	_, err = svc.ModifyAppConfig(tenantCtx, []byte(`{"org_info": {"org_name": "Initech"}}`), fleet.ApplySpecOptions{})
+	checkAuthErr(t, true, err)
+	require.False(t, ds.SaveAppConfigFuncInvoked)
+
+	// the users outside any tenant get the global config
+	ds.TenantAppConfigFuncInvoked = false
+	ac, err = svc.AppConfigObfuscated(viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}}))
+	require.NoError(t, err)
+	require.Equal(t, "Acme", ac.OrgInfo.OrgName)
+	require.False(t, ds.TenantAppConfigFuncInvoked)
+}
+
 // TestVersion tests that all users can access the version endpoint.
 func TestVersion(t *testing.T) {
 	ds := new(mock.Store)
@@ -298,6 +340,67 @@ func TestApplyEnrollSecretWithGlobalEnrollConfig(t *testing.T) {
 	require.False(t, ds.ApplyEnrollSecretsFuncInvoked)
 }
 
+func TestApplyEnrollSecretHostRules(t *testing.T) {
+	ds := new(mock.Store)
+	ds.ApplyEnrollSecretsFunc = func(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostCustomFields: []fleet.HostCustomFieldDefinition{
+			{Name: "role", Type: fleet.HostCustomFieldTypeString},
+			{Name: "floor", Type: fleet.HostCustomFieldTypeNumber},
+		}}, nil
+	}
+	ds.GetLabelSpecFunc = func(ctx context.Context, name string) (*fleet.LabelSpec, error) {
+		switch name {
+		case "kiosk":
+			return &fleet.LabelSpec{Name: name, LabelMembershipType: fleet.LabelMembershipTypeManual}, nil
+		case "macs":
+			return &fleet.LabelSpec{Name: name, LabelMembershipType: fleet.LabelMembershipTypeDynamic}, nil
+		case "All Hosts":
+			return &fleet.LabelSpec{Name: name, LabelType: fleet.LabelTypeBuiltIn, LabelMembershipType: fleet.LabelMembershipTypeManual}, nil
+		default:
+			return nil, &notFoundError{}
+		}
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	cases := []struct {
+		desc    string
+		secret  *fleet.EnrollSecret
+		wantErr string
+	}{
+		{"no rules", &fleet.EnrollSecret{Secret: "ABC"}, ""},
+		{"valid rules", &fleet.EnrollSecret{Secret: "ABC", Labels: []string{"kiosk"}, CustomFields: fleet.HostCustomFieldValues{"role": " kiosk ", "floor": "2"}}, ""},
+		{"unknown label", &fleet.EnrollSecret{Secret: "ABC", Labels: []string{"nope"}}, `label "nope" does not exist`},
+		{"dynamic label", &fleet.EnrollSecret{Secret: "ABC", Labels: []string{"macs"}}, `label "macs" is not a manual label`},
+		{"builtin label", &fleet.EnrollSecret{Secret: "ABC", Labels: []string{"All Hosts"}}, `label "All Hosts" is not a manual label`},
+		{"duplicate label", &fleet.EnrollSecret{Secret: "ABC", Labels: []string{"kiosk", "kiosk"}}, `duplicate label "kiosk"`},
+		{"undefined custom field", &fleet.EnrollSecret{Secret: "ABC", CustomFields: fleet.HostCustomFieldValues{"owner": "x"}}, `custom field "owner" is not defined`},
+		{"invalid custom field value", &fleet.EnrollSecret{Secret: "ABC", CustomFields: fleet.HostCustomFieldValues{"floor": "x"}}, `the value of "floor" must be a number`},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			ds.ApplyEnrollSecretsFuncInvoked = false
+			err := svc.ApplyEnrollSecretSpec(ctx, &fleet.EnrollSecretSpec{Secrets: []*fleet.EnrollSecret{c.secret}})
+			if c.wantErr != "" {
+				require.ErrorContains(t, err, c.wantErr)
+				require.False(t, ds.ApplyEnrollSecretsFuncInvoked)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ds.ApplyEnrollSecretsFuncInvoked)
+		})
+	}
+
+	// custom field values are normalized
+	spec := &fleet.EnrollSecretSpec{Secrets: []*fleet.EnrollSecret{{Secret: "ABC", CustomFields: fleet.HostCustomFieldValues{"role": " kiosk "}}}}
+	require.NoError(t, svc.ApplyEnrollSecretSpec(ctx, spec))
+	require.Equal(t, "kiosk", spec.Secrets[0].CustomFields["role"])
+}
+
 func TestCertificateChain(t *testing.T) {
 	server, teardown := setupCertificateChain(t)
 	defer teardown()
@@ -603,6 +706,9 @@ func TestModifyAppConfigSMTPConfigured(t *testing.T) {
 		*dsAppConfig = *conf
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	// Disable SMTP.
 	newAppConfig := fleet.AppConfig{
@@ -707,6 +813,9 @@ func TestTransparencyURL(t *testing.T) {
 				*dsAppConfig = *conf
 				return nil
 			}
+			ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+				return &fleet.ConfigVersion{}, nil
+			}
 
 			ac, err := svc.AppConfigObfuscated(ctx)
 			require.NoError(t, err)
@@ -756,6 +865,9 @@ func TestTransparencyURLDowngradeLicense(t *testing.T) {
 		*dsAppConfig = *conf
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	ac, err := svc.AppConfigObfuscated(ctx)
 	require.NoError(t, err)
@@ -992,6 +1104,9 @@ func TestMDMAppleConfig(t *testing.T) {
 				*dsAppConfig = *conf
 				return nil
 			}
+			ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+				return &fleet.ConfigVersion{}, nil
+			}
 			ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
 				if tt.findTeam {
 					return &fleet.Team{}, nil
@@ -1090,6 +1205,9 @@ func TestModifyAppConfigSMTPSSOAgentOptions(t *testing.T) {
 		*dsAppConfig = *conf
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -1221,6 +1339,9 @@ func TestModifyEnableAnalytics(t *testing.T) {
 				*dsAppConfig = *conf
 				return nil
 			}
+			ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+				return &fleet.ConfigVersion{}, nil
+			}
 
 			ac, err := svc.AppConfigObfuscated(ctx)
 			require.NoError(t, err)
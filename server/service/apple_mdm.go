@@ -3,8 +3,11 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha1" //nolint:gosec // used for the certificate fingerprints
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,6 +25,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/authz"
 	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxdb"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
@@ -35,6 +39,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
 	nano_service "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/service"
 	"github.com/fleetdm/fleet/v4/server/sso"
+	"github.com/fleetdm/fleet/v4/server/worker"
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/google/uuid"
@@ -140,6 +145,12 @@ func (svc *Service) GetMDMAppleCommandResults(ctx context.Context, commandUUID s
 			res.Hostname = hostsByUUID[res.HostUUID].Hostname
 		}
 	}
+
+	// the payloads of the commands that install configuration profiles
+	// contain the values of the secret variables the profiles reference.
+	if err := redactCommandResultsSecretVariables(ctx, svc.ds, results); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "redact secret variables from command payloads")
+	}
 	return results, nil
 }
 
@@ -301,7 +312,7 @@ func newMDMAppleConfigProfileEndpoint(ctx context.Context, request interface{},
 	}
 	defer ff.Close()
 	// providing an empty set of labels since this endpoint is only maintained for backwards compat
-	cp, err := svc.NewMDMAppleConfigProfile(ctx, req.TeamID, ff, nil)
+	cp, err := svc.NewMDMAppleConfigProfile(ctx, req.TeamID, ff, fleet.MDMProfileLabelTargeting{}, nil)
 	if err != nil {
 		return &newMDMAppleConfigProfileResponse{Err: err}, nil
 	}
@@ -310,7 +321,7 @@ func newMDMAppleConfigProfileEndpoint(ctx context.Context, request interface{},
 	}, nil
 }
 
-func (svc *Service) NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r io.Reader, labels []string) (*fleet.MDMAppleConfigProfile, error) {
+func (svc *Service) NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r io.Reader, labels fleet.MDMProfileLabelTargeting, rollout *fleet.MDMProfileRolloutSettings) (*fleet.MDMAppleConfigProfile, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: &teamID}, fleet.ActionWrite); err != nil {
 		return nil, ctxerr.Wrap(ctx, err)
 	}
@@ -349,6 +360,9 @@ func (svc *Service) NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r
 	if err := cp.ValidateUserProvided(); err != nil {
 		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: err.Error()})
 	}
+	if err := validateSecretVariables(ctx, svc.ds, "profile", string(b)); err != nil {
+		return nil, err
+	}
 
 	labelMap, err := svc.validateProfileLabels(ctx, labels)
 	if err != nil {
@@ -356,6 +370,13 @@ func (svc *Service) NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r
 	}
 	cp.Labels = labelMap
 
+	if rollout != nil {
+		if err := rollout.Validate(); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "validating rollout")
+		}
+		cp.Rollout = rollout
+	}
+
 	newCP, err := svc.ds.NewMDMAppleConfigProfile(ctx, *cp)
 	if err != nil {
 		var existsErr existsErrorInterface
@@ -391,6 +412,9 @@ func (svc *Service) NewMDMAppleConfigProfile(ctx context.Context, teamID uint, r
 	}); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "logging activity for create mdm apple config profile")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return newCP, nil
 }
@@ -477,6 +501,9 @@ func (svc *Service) NewMDMAppleDeclaration(ctx context.Context, teamID uint, r i
 	}); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "logging activity for create mdm apple declaration")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return decl, nil
 }
@@ -779,6 +806,9 @@ func (svc *Service) DeleteMDMAppleConfigProfile(ctx context.Context, profileUUID
 	}); err != nil {
 		return ctxerr.Wrap(ctx, err, "logging activity for delete mdm apple config profile")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return nil
 }
@@ -858,6 +888,9 @@ func (svc *Service) DeleteMDMAppleDeclaration(ctx context.Context, declUUID stri
 	}); err != nil {
 		return ctxerr.Wrap(ctx, err, "logging activity for delete mdm apple declaration")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return nil
 }
@@ -1653,6 +1686,298 @@ func (svc *Service) MDMAppleEraseDevice(ctx context.Context, hostID uint) error
 	return fleet.ErrMissingLicense
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Restart a device
+////////////////////////////////////////////////////////////////////////////////
+
+type restartDeviceRequest struct {
+	HostID uint `url:"id"`
+}
+
+type restartDeviceResponse struct {
+	CommandUUID string `json:"command_uuid,omitempty"`
+	Err         error  `json:"error,omitempty"`
+}
+
+func (r restartDeviceResponse) error() error { return r.Err }
+
+func restartDeviceEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*restartDeviceRequest)
+	cmdUUID, err := svc.MDMAppleRestartDevice(ctx, req.HostID)
+	if err != nil {
+		return restartDeviceResponse{Err: err}, nil
+	}
+	return restartDeviceResponse{CommandUUID: cmdUUID}, nil
+}
+
+func (svc *Service) MDMAppleRestartDevice(ctx context.Context, hostID uint) (string, error) {
+	return svc.enqueueMDMAppleHostCommand(ctx, hostID, "restart", nil, svc.mdmAppleCommander.RestartDevice,
+		func(host *fleet.Host) fleet.ActivityDetails {
+			return fleet.ActivityTypeRestartedHost{HostID: host.ID, HostDisplayName: host.DisplayName()}
+		})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Shut down a device
+////////////////////////////////////////////////////////////////////////////////
+
+type shutDownDeviceRequest struct {
+	HostID uint `url:"id"`
+}
+
+type shutDownDeviceResponse struct {
+	CommandUUID string `json:"command_uuid,omitempty"`
+	Err         error  `json:"error,omitempty"`
+}
+
+func (r shutDownDeviceResponse) error() error { return r.Err }
+
+func shutDownDeviceEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*shutDownDeviceRequest)
+	cmdUUID, err := svc.MDMAppleShutDownDevice(ctx, req.HostID)
+	if err != nil {
+		return shutDownDeviceResponse{Err: err}, nil
+	}
+	return shutDownDeviceResponse{CommandUUID: cmdUUID}, nil
+}
+
+func (svc *Service) MDMAppleShutDownDevice(ctx context.Context, hostID uint) (string, error) {
+	return svc.enqueueMDMAppleHostCommand(ctx, hostID, "shut down", nil, svc.mdmAppleCommander.ShutDownDevice,
+		func(host *fleet.Host) fleet.ActivityDetails {
+			return fleet.ActivityTypeShutDownHost{HostID: host.ID, HostDisplayName: host.DisplayName()}
+		})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Unlock a user account
+////////////////////////////////////////////////////////////////////////////////
+
+type unlockUserAccountRequest struct {
+	HostID   uint   `url:"id"`
+	Username string `json:"username"`
+}
+
+type unlockUserAccountResponse struct {
+	CommandUUID string `json:"command_uuid,omitempty"`
+	Err         error  `json:"error,omitempty"`
+}
+
+func (r unlockUserAccountResponse) error() error { return r.Err }
+
+func unlockUserAccountEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*unlockUserAccountRequest)
+	cmdUUID, err := svc.MDMAppleUnlockUserAccount(ctx, req.HostID, req.Username)
+	if err != nil {
+		return unlockUserAccountResponse{Err: err}, nil
+	}
+	return unlockUserAccountResponse{CommandUUID: cmdUUID}, nil
+}
+
+func (svc *Service) MDMAppleUnlockUserAccount(ctx context.Context, hostID uint, username string) (string, error) {
+	username = strings.TrimSpace(username)
+	return svc.enqueueMDMAppleHostCommand(ctx, hostID, "unlock a user account of",
+		func(host *fleet.Host) error {
+			if host.Platform != "darwin" {
+				return fleet.NewInvalidArgumentError("host_id", "Can't unlock a user account of the host because it's not a macOS host.")
+			}
+			if username == "" {
+				return fleet.NewInvalidArgumentError("username", "The username of the account to unlock is required.")
+			}
+			return nil
+		},
+		func(ctx context.Context, hostUUIDs []string, uuid string) error {
+			return svc.mdmAppleCommander.UnlockUserAccount(ctx, hostUUIDs, uuid, username)
+		},
+		func(host *fleet.Host) fleet.ActivityDetails {
+			return fleet.ActivityTypeUnlockedHostUserAccount{HostID: host.ID, HostDisplayName: host.DisplayName(), Username: username}
+		})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Repair the MDM enrollment of a host
+////////////////////////////////////////////////////////////////////////////////
+
+type repairHostMDMRequest struct {
+	HostID uint `url:"id"`
+}
+
+type repairHostMDMResponse struct {
+	*fleet.MDMAppleHostRepair
+	Err error `json:"error,omitempty"`
+}
+
+func (r repairHostMDMResponse) error() error { return r.Err }
+
+func repairHostMDMEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*repairHostMDMRequest)
+	repair, err := svc.MDMAppleRepairHost(ctx, req.HostID)
+	if err != nil {
+		return repairHostMDMResponse{Err: err}, nil
+	}
+	return repairHostMDMResponse{MDMAppleHostRepair: repair}, nil
+}
+
+func (svc *Service) MDMAppleRepairHost(ctx context.Context, hostID uint) (*fleet.MDMAppleHostRepair, error) {
+	var (
+		repair fleet.MDMAppleHostRepair
+		host   *fleet.Host
+	)
+	_, err := svc.enqueueMDMAppleHostCommand(ctx, hostID, "repair the MDM enrollment of",
+		func(h *fleet.Host) error {
+			host = h
+			return nil
+		},
+		func(ctx context.Context, hostUUIDs []string, devInfoUUID string) error {
+			// all the commands are sent to the host, the failures to send the
+			// push notification are only reported once they are enqueued.
+			var apnsErr *apple_mdm.APNSDeliveryError
+			ignoreAPNSErr := func(err error) error {
+				if errors.As(err, &apnsErr) {
+					return nil
+				}
+				return err
+			}
+
+			// renew the SCEP identity certificate
+			if svc.config.MDM.IsAppleSCEPSet() {
+				assoc, err := svc.ds.GetHostSCEPIdentityAssociation(ctx, host.UUID)
+				if err != nil && !fleet.IsNotFound(err) {
+					return ctxerr.Wrap(ctx, err, "get host SCEP identity association")
+				}
+				if assoc != nil {
+					appConfig, err := svc.ds.AppConfig(ctx)
+					if err != nil {
+						return ctxerr.Wrap(ctx, err, "get app config")
+					}
+					topic, err := svc.config.MDM.AppleAPNsTopic()
+					if err != nil {
+						return ctxerr.Wrap(ctx, err, "get certificate topic")
+					}
+					cmdUUID, err := sendSCEPRenewalProfile(ctx, svc.ds, &svc.config, appConfig, svc.mdmAppleCommander, scepAssociationTopic(*assoc, topic),
+						[]fleet.SCEPIdentityAssociation{*assoc}, assoc.EnrollReference)
+					if err := ignoreAPNSErr(err); err != nil {
+						return ctxerr.Wrap(ctx, err, "renew SCEP identity certificate")
+					}
+					repair.SCEPRenewalCommandUUID = cmdUUID
+				}
+			}
+
+			// install fleetd
+			if host.Platform == "darwin" {
+				cmdUUID := uuid.NewString()
+				err := svc.mdmAppleCommander.InstallEnterpriseApplication(ctx, hostUUIDs, cmdUUID, apple_mdm.FleetdPublicManifestURL)
+				if err := ignoreAPNSErr(err); err != nil {
+					return ctxerr.Wrap(ctx, err, "send fleetd install command")
+				}
+				if err := svc.ds.RecordHostFleetdInstall(ctx, cmdUUID, host.UUID, 0); err != nil {
+					return ctxerr.Wrap(ctx, err, "record fleetd install")
+				}
+				repair.FleetdInstallCommandUUID = cmdUUID
+			}
+
+			// send the configuration profiles of the host again, they are
+			// delivered by the next run of the profiles cron job.
+			if err := svc.ds.BulkSetPendingMDMHostProfiles(ctx, []uint{host.ID}, nil, nil, nil); err != nil {
+				return ctxerr.Wrap(ctx, err, "set pending host profiles")
+			}
+			profs, err := svc.ds.GetHostMDMAppleProfiles(ctx, host.UUID)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "get host profiles")
+			}
+			for _, prof := range profs {
+				if !strings.HasPrefix(prof.ProfileUUID, fleet.MDMAppleProfileUUIDPrefix) ||
+					prof.OperationType != fleet.MDMOperationTypeInstall ||
+					prof.Status == nil || *prof.Status == fleet.MDMDeliveryPending {
+					continue
+				}
+				if err := svc.ds.ResendHostMDMProfile(ctx, host.UUID, prof.ProfileUUID); err != nil {
+					return ctxerr.Wrap(ctx, err, "resend host profile")
+				}
+				repair.ResentProfiles++
+			}
+
+			// refresh the device information
+			err = svc.mdmAppleCommander.DeviceInformation(ctx, hostUUIDs, devInfoUUID, nil)
+			if err := ignoreAPNSErr(err); err != nil {
+				return ctxerr.Wrap(ctx, err, "send DeviceInformation command")
+			}
+			repair.DeviceInformationCommandUUID = devInfoUUID
+			return nil
+		},
+		func(host *fleet.Host) fleet.ActivityDetails {
+			return fleet.ActivityTypeRepairedHostMDM{HostID: host.ID, HostDisplayName: host.DisplayName()}
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &repair, nil
+}
+
+// enqueueMDMAppleHostCommand validates that the Apple host can receive an MDM
+// command for the action, enqueues the command with send and creates the
+// activity returned by newActivity. It returns the UUID of the command. If
+// validate is not nil, it is called once the user is authorized to validate
+// that the command applies to the host.
+func (svc *Service) enqueueMDMAppleHostCommand(
+	ctx context.Context,
+	hostID uint,
+	action string,
+	validate func(host *fleet.Host) error,
+	send func(ctx context.Context, hostUUIDs []string, uuid string) error,
+	newActivity func(host *fleet.Host) fleet.ActivityDetails,
+) (string, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return "", err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	// Authorize again with team loaded now that we have the host's team_id.
+	if err := svc.authz.Authorize(ctx, fleet.MDMCommandAuthz{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return "", err
+	}
+
+	if !fleet.IsApplePlatform(host.Platform) {
+		return "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Can't %s the host because it's not a macOS, iOS or iPadOS host.", action)))
+	}
+	if validate != nil {
+		if err := validate(host); err != nil {
+			return "", ctxerr.Wrap(ctx, err, "validate host")
+		}
+	}
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return "", ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	if !hostMDM.IsFleetEnrolled() {
+		return "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id",
+			fmt.Sprintf("Can't %s the host because it doesn't have MDM turned on.", action)))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return "", fleet.ErrNoContext
+	}
+
+	// a failure to send the push notification is not an error, the command is
+	// enqueued and will be delivered on the next check-in of the host.
+	cmdUUID := uuid.NewString()
+	var apnsErr *apple_mdm.APNSDeliveryError
+	if err := send(ctx, []string{host.UUID}, cmdUUID); err != nil && !errors.As(err, &apnsErr) {
+		return "", ctxerr.Wrapf(ctx, err, "enqueue %s command", action)
+	}
+
+	if err := svc.ds.NewActivity(ctx, vc.User, newActivity(host)); err != nil {
+		return "", ctxerr.Wrapf(ctx, err, "create activity for %s host request", action)
+	}
+	return cmdUUID, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Get profiles assigned to a host
 ////////////////////////////////////////////////////////////////////////////////
@@ -1806,6 +2131,11 @@ func (svc *Service) BatchSetMDMAppleProfiles(ctx context.Context, tmID *uint, tm
 	if dryRun {
 		return nil
 	}
+	for i, prof := range profiles {
+		if err := validateSecretVariables(ctx, svc.ds, fmt.Sprintf("profiles[%d]", i), string(prof)); err != nil {
+			return err
+		}
+	}
 	if err := svc.ds.BatchSetMDMAppleProfiles(ctx, tmID, profs); err != nil {
 		return err
 	}
@@ -1826,6 +2156,9 @@ func (svc *Service) BatchSetMDMAppleProfiles(ctx context.Context, tmID *uint, tm
 	}); err != nil {
 		return ctxerr.Wrap(ctx, err, "logging activity for edited macos profile")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, tmID, authz.UserFromContext(ctx)); err != nil {
+		return ctxerr.Wrap(ctx, err, "record config version")
+	}
 	return nil
 }
 
@@ -2496,7 +2829,7 @@ func (svc *MDMAppleCheckinAndCommandService) Authenticate(r *mdm.Request, m *mdm
 
 	err = svc.mdmLifecycle.Do(r.Context, mdmlifecycle.HostOptions{
 		Action:         mdmlifecycle.HostActionReset,
-		Platform:       "darwin",
+		Platform:       fleet.ApplePlatformFromModel(m.ModelName),
 		UUID:           m.UDID,
 		HardwareSerial: m.SerialNumber,
 		HardwareModel:  m.Model,
@@ -2521,10 +2854,16 @@ func (svc *MDMAppleCheckinAndCommandService) Authenticate(r *mdm.Request, m *mdm
 
 // TokenUpdate handles MDM [TokenUpdate][1] requests.
 //
-// This method is executed after the request has been handled by nanomdm.
+// This method is executed after the request has been handled by nanomdm. The
+// first TokenUpdate of an enrollment completes it, the next ones are sent
+// when the push token changes.
 //
 // [1]: https://developer.apple.com/documentation/devicemanagement/token_update
 func (svc *MDMAppleCheckinAndCommandService) TokenUpdate(r *mdm.Request, m *mdm.TokenUpdate) error {
+	if r.ParentID != "" {
+		return svc.userChannelTokenUpdate(r)
+	}
+
 	info, err := svc.ds.GetHostMDMCheckinInfo(r.Context, r.ID)
 	if err != nil {
 		return ctxerr.Wrap(r.Context, err, "getting checkin info")
@@ -2536,12 +2875,85 @@ func (svc *MDMAppleCheckinAndCommandService) TokenUpdate(r *mdm.Request, m *mdm.
 		return ctxerr.Wrap(r.Context, err, "cleaning SCEP refs")
 	}
 
-	return svc.mdmLifecycle.Do(r.Context, mdmlifecycle.HostOptions{
+	err = svc.mdmLifecycle.Do(r.Context, mdmlifecycle.HostOptions{
 		Action:          mdmlifecycle.HostActionTurnOn,
 		Platform:        "darwin",
 		UUID:            r.ID,
 		EnrollReference: r.Params[mobileconfig.FleetEnrollReferenceKey],
 	})
+	if err != nil {
+		return err
+	}
+
+	first, err := svc.isFirstTokenUpdate(r.Context, r.ID)
+	if err != nil || !first {
+		return err
+	}
+	svc.sendMDMEnrollmentWebhook(r.Context, fleet.MDMEnrollmentEventEnrolled, r.ID, info)
+	return nil
+}
+
+// userChannelTokenUpdate handles the TokenUpdate requests of the user
+// channel of a device, which is established when a user logs in.
+func (svc *MDMAppleCheckinAndCommandService) userChannelTokenUpdate(r *mdm.Request) error {
+	first, err := svc.isFirstTokenUpdate(r.Context, r.ID)
+	if err != nil || !first {
+		return err
+	}
+
+	info, err := svc.ds.GetHostMDMCheckinInfo(r.Context, r.ParentID)
+	if err != nil {
+		return ctxerr.Wrap(r.Context, err, "getting checkin info")
+	}
+	if err := svc.ds.NewActivity(r.Context, nil, &fleet.ActivityTypeMDMUserChannelEstablished{
+		HostSerial:      info.HardwareSerial,
+		HostDisplayName: info.DisplayName,
+	}); err != nil {
+		return ctxerr.Wrap(r.Context, err, "create activity for user channel")
+	}
+	svc.sendMDMEnrollmentWebhook(r.Context, fleet.MDMEnrollmentEventUserChannelEstablished, r.ParentID, info)
+	return nil
+}
+
+// isFirstTokenUpdate returns true if the TokenUpdate request handled for the
+// enrollment is the first one, i.e. the one that completes the enrollment.
+func (svc *MDMAppleCheckinAndCommandService) isFirstTokenUpdate(ctx context.Context, enrollmentID string) (bool, error) {
+	nanoEnroll, err := svc.ds.GetNanoMDMEnrollment(ctx, enrollmentID)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "retrieving nano enrollment info")
+	}
+	return nanoEnroll != nil && nanoEnroll.Enabled && nanoEnroll.TokenUpdateTally == 1, nil
+}
+
+// sendMDMEnrollmentWebhook sends the MDM enrollment event of the host to the
+// MDM enrollment webhook, if enabled. Failures are only logged so that they
+// don't fail the check-in.
+func (svc *MDMAppleCheckinAndCommandService) sendMDMEnrollmentWebhook(ctx context.Context, event, hostUUID string, info *fleet.HostMDMCheckinInfo) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		level.Error(svc.logger).Log("msg", "get app config for MDM enrollment webhook", "host_uuid", hostUUID, "err", err)
+		return
+	}
+	settings := appConfig.WebhookSettings.MDMEnrollmentWebhook
+	if !settings.Enable {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"text": fmt.Sprintf("MDM event %q received for host %s. "+
+			"You've been sent this message because the MDM enrollment webhook is enabled in your Fleet instance.", event, info.DisplayName),
+		"data": map[string]interface{}{
+			"event":              event,
+			"host_uuid":          hostUUID,
+			"host_serial":        info.HardwareSerial,
+			"host_display_name":  info.DisplayName,
+			"host_platform":      info.Platform,
+			"installed_from_dep": info.InstalledFromDEP,
+		},
+	}
+	if err := server.PostJSONWithTimeout(ctx, settings.DestinationURL, &payload); err != nil {
+		level.Error(svc.logger).Log("msg", "send MDM enrollment webhook", "host_uuid", hostUUID, "event", event, "err", err)
+	}
 }
 
 // CheckOut handles MDM [CheckOut][1] requests.
@@ -2552,6 +2964,11 @@ func (svc *MDMAppleCheckinAndCommandService) TokenUpdate(r *mdm.Request, m *mdm.
 //
 // [1]: https://developer.apple.com/documentation/devicemanagement/check_out
 func (svc *MDMAppleCheckinAndCommandService) CheckOut(r *mdm.Request, m *mdm.CheckOut) error {
+	if r.ParentID != "" {
+		// only the user channel is removed, the device stays enrolled.
+		return nil
+	}
+
 	info, err := svc.ds.GetHostMDMCheckinInfo(r.Context, m.Enrollment.UDID)
 	if err != nil {
 		return err
@@ -2566,11 +2983,15 @@ func (svc *MDMAppleCheckinAndCommandService) CheckOut(r *mdm.Request, m *mdm.Che
 		return err
 	}
 
-	return svc.ds.NewActivity(r.Context, nil, &fleet.ActivityTypeMDMUnenrolled{
+	if err := svc.ds.NewActivity(r.Context, nil, &fleet.ActivityTypeMDMUnenrolled{
 		HostSerial:       info.HardwareSerial,
 		HostDisplayName:  info.DisplayName,
 		InstalledFromDEP: info.InstalledFromDEP,
-	})
+	}); err != nil {
+		return err
+	}
+	svc.sendMDMEnrollmentWebhook(r.Context, fleet.MDMEnrollmentEventUnenrolled, r.ID, info)
+	return nil
 }
 
 // SetBootstrapToken handles MDM [SetBootstrapToken][1] requests.
@@ -2616,6 +3037,14 @@ func (svc *MDMAppleCheckinAndCommandService) DeclarativeManagement(r *mdm.Reques
 //
 // [1]: https://developer.apple.com/documentation/devicemanagement/commands_and_queries
 func (svc *MDMAppleCheckinAndCommandService) CommandAndReportResults(r *mdm.Request, cmdResult *mdm.CommandResults) (*mdm.Command, error) {
+	// every device connection to the command endpoint reports either a
+	// result or an Idle status, use it to keep track of the host's online
+	// status (this is the only channel for MDM-only devices such as iPhones).
+	// A failure to do so must not prevent processing the command results.
+	if err := svc.ds.MarkHostMDMSeen(r.Context, cmdResult.UDID, time.Now()); err != nil {
+		level.Error(svc.logger).Log("msg", "mark host MDM seen", "host_uuid", cmdResult.UDID, "err", err)
+	}
+
 	if cmdResult.Status == "Idle" {
 		// macOS hosts are considered unlocked if they are online any time
 		// after they have been unlocked. If the host has been seen after a
@@ -2640,14 +3069,19 @@ func (svc *MDMAppleCheckinAndCommandService) CommandAndReportResults(r *mdm.Requ
 
 	switch requestType {
 	case "InstallProfile":
-		return nil, apple_mdm.HandleHostMDMProfileInstallResult(
+		if err := apple_mdm.HandleHostMDMProfileInstallResult(
 			r.Context,
 			svc.ds,
 			cmdResult.UDID,
 			cmdResult.CommandUUID,
 			mdmAppleDeliveryStatusFromCommandStatus(cmdResult.Status),
 			apple_mdm.FmtErrorChain(cmdResult.ErrorChain),
-		)
+		); err != nil {
+			return nil, err
+		}
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.recordProfileCertificate(r.Context, cmdResult.UDID, cmdResult.CommandUUID)
+		}
 	case "RemoveProfile":
 		return nil, svc.ds.UpdateOrDeleteHostMDMAppleProfile(r.Context, &fleet.HostMDMAppleProfile{
 			CommandUUID:   cmdResult.CommandUUID,
@@ -2663,6 +3097,69 @@ func (svc *MDMAppleCheckinAndCommandService) CommandAndReportResults(r *mdm.Requ
 			cmdResult.Status == fleet.MDMAppleStatusCommandFormatError {
 			return nil, svc.ds.UpdateHostLockWipeStatusFromAppleMDMResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, requestType, cmdResult.Status == fleet.MDMAppleStatusAcknowledged)
 		}
+	case "EnableLostMode", "DisableLostMode":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged ||
+			cmdResult.Status == fleet.MDMAppleStatusError ||
+			cmdResult.Status == fleet.MDMAppleStatusCommandFormatError {
+			return nil, svc.ds.UpdateHostLostModeFromAppleMDMResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, requestType, cmdResult.Status == fleet.MDMAppleStatusAcknowledged)
+		}
+	case "DeviceLocation":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.handleDeviceLocationAck(r.Context, cmdResult)
+		}
+	case "ActivationLockBypassCode":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.handleActivationLockBypassCodeAck(r.Context, cmdResult)
+		}
+	case "ClearActivationLockBypassCode":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged ||
+			cmdResult.Status == fleet.MDMAppleStatusError ||
+			cmdResult.Status == fleet.MDMAppleStatusCommandFormatError {
+			return nil, svc.ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, cmdResult.Status == fleet.MDMAppleStatusAcknowledged)
+		}
+	case "SetRecoveryLock":
+		switch cmdResult.Status {
+		case fleet.MDMAppleStatusAcknowledged:
+			return nil, svc.handleSetRecoveryLockAck(r.Context, cmdResult)
+		case fleet.MDMAppleStatusError, fleet.MDMAppleStatusCommandFormatError:
+			errMsg := strings.TrimSpace(apple_mdm.FmtErrorChain(cmdResult.ErrorChain))
+			if errMsg == "" {
+				errMsg = cmdResult.Status
+			}
+			_, err := svc.ds.UpdateHostMDMAppleRecoveryLockFromSetResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, errMsg)
+			return nil, ctxerr.Wrap(r.Context, err, "update recovery lock from failed SetRecoveryLock result")
+		}
+	case "VerifyRecoveryLock":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.handleVerifyRecoveryLockAck(r.Context, cmdResult)
+		}
+	case "SetFirmwarePassword":
+		switch cmdResult.Status {
+		case fleet.MDMAppleStatusAcknowledged:
+			return nil, svc.handleSetFirmwarePasswordAck(r.Context, cmdResult)
+		case fleet.MDMAppleStatusError, fleet.MDMAppleStatusCommandFormatError:
+			errMsg := strings.TrimSpace(apple_mdm.FmtErrorChain(cmdResult.ErrorChain))
+			if errMsg == "" {
+				errMsg = cmdResult.Status
+			}
+			_, err := svc.ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, errMsg)
+			return nil, ctxerr.Wrap(r.Context, err, "update firmware password from failed SetFirmwarePassword result")
+		}
+	case "VerifyFirmwarePassword":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.handleVerifyFirmwarePasswordAck(r.Context, cmdResult)
+		}
+	case "RotateFileVaultKey":
+		switch cmdResult.Status {
+		case fleet.MDMAppleStatusAcknowledged:
+			return nil, svc.handleRotateFileVaultKeyAck(r.Context, cmdResult)
+		case fleet.MDMAppleStatusError, fleet.MDMAppleStatusCommandFormatError:
+			// the host keeps its current key, clear the pending rotation so
+			// that the key can be rotated again.
+			level.Info(svc.logger).Log("msg", "FileVault key rotation failed", "host_uuid", cmdResult.UDID, "err", apple_mdm.FmtErrorChain(cmdResult.ErrorChain))
+			err := svc.ds.UpdateHostDiskEncryptionKeyFromRotateResult(r.Context, cmdResult.UDID, cmdResult.CommandUUID, "")
+			return nil, ctxerr.Wrap(r.Context, err, "update disk encryption key from failed RotateFileVaultKey result")
+		}
 	case "DeclarativeManagement":
 		// set "pending-install" profiles to "verifying" or "failed"
 		// depending on the status of the DeviceManagement command
@@ -2670,57 +3167,645 @@ func (svc *MDMAppleCheckinAndCommandService) CommandAndReportResults(r *mdm.Requ
 		detail := fmt.Sprintf("%s. Make sure the host is on macOS 13 or higher.", apple_mdm.FmtErrorChain(cmdResult.ErrorChain))
 		err := svc.ds.MDMAppleSetPendingDeclarationsAs(r.Context, cmdResult.UDID, status, detail)
 		return nil, ctxerr.Wrap(r.Context, err, "update declaration status on DeclarativeManagement ack")
-
+	case "InstallEnterpriseApplication":
+		if cmdResult.Status == fleet.MDMAppleStatusError || cmdResult.Status == fleet.MDMAppleStatusCommandFormatError {
+			return nil, svc.handleFleetdInstallFailure(r.Context, cmdResult)
+		}
+	case "RemoveApplication":
+		if cmdResult.Status == fleet.MDMAppleStatusAcknowledged {
+			return nil, svc.handleRemoveApplicationAck(r.Context, cmdResult)
+		}
+	case "DeviceInformation", "InstalledApplicationList", "SecurityInfo", "CertificateList":
+		// only the results of the commands sent to refetch the details of
+		// the hosts are ingested.
+		if cmdResult.Status != fleet.MDMAppleStatusAcknowledged ||
+			!strings.HasPrefix(cmdResult.CommandUUID, fleet.RefetchMDMCommandUUIDPrefix) {
+			return nil, nil
+		}
+		return nil, svc.ingestRefetchResults(r.Context, requestType, cmdResult)
 	}
 
 	return nil, nil
 }
 
-// mdmAppleDeliveryStatusFromCommandStatus converts a MDM command status to a
-// fleet.MDMAppleDeliveryStatus.
-//
-// NOTE: this mapping does not include all
-// possible delivery statuses (e.g., verified status is not included) is intended to
-// only be used in the context of CommandAndReportResults in the MDMAppleCheckinAndCommandService.
-// Extra care should be taken before using this function in other contexts.
-func mdmAppleDeliveryStatusFromCommandStatus(cmdStatus string) *fleet.MDMDeliveryStatus {
-	switch cmdStatus {
-	case fleet.MDMAppleStatusAcknowledged:
-		return &fleet.MDMDeliveryVerifying
-	case fleet.MDMAppleStatusError, fleet.MDMAppleStatusCommandFormatError:
-		return &fleet.MDMDeliveryFailed
-	case fleet.MDMAppleStatusIdle, fleet.MDMAppleStatusNotNow:
-		return &fleet.MDMDeliveryPending
-	default:
+// handleRemoveApplicationAck removes the app from the software inventory of
+// the host that acknowledged a RemoveApplication command, so that it is not
+// reported as installed until the next inventory of the host.
+func (svc *MDMAppleCheckinAndCommandService) handleRemoveApplicationAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	// the result was just stored by nanomdm, read it from the primary.
+	results, err := svc.ds.GetMDMAppleCommandResults(ctxdb.RequirePrimary(ctx, true), cmdResult.CommandUUID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get RemoveApplication command")
+	}
+	var payload []byte
+	for _, res := range results {
+		if res.HostUUID == cmdResult.UDID {
+			payload = res.Payload
+			break
+		}
+	}
+	if payload == nil {
 		return nil
 	}
-}
 
-// ensureFleetProfiles ensures there's a fleetd configuration profile in
-// mdm_apple_configuration_profiles for each team and for "no team"
-//
-// We try our best to use each team's secret but we default to creating a
-// profile with the global enroll secret if the team doesn't have any enroll
-// secrets.
-//
-// This profile will be installed to all hosts in the team (or "no team",) but it
-// will only be used by hosts that have a fleetd installation without an enroll
-// secret and fleet URL (mainly DEP enrolled hosts).
-func ensureFleetProfiles(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, signingCert *tls.Certificate) error {
-	appCfg, err := ds.AppConfig(ctx)
-	if err != nil {
-		return ctxerr.Wrap(ctx, err, "fetching app config")
+	var cmd struct {
+		Command struct {
+			Identifier string
+		}
 	}
-
-	var rootCAProfContents bytes.Buffer
-	params := mobileconfig.FleetCARootTemplateOptions{
-		PayloadIdentifier: mobileconfig.FleetCARootConfigPayloadIdentifier,
-		PayloadName:       mdm_types.FleetCAConfigProfileName,
-		Certificate:       base64.StdEncoding.EncodeToString(signingCert.Certificate[0]),
+	if err := plist.Unmarshal(payload, &cmd); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal RemoveApplication command")
 	}
-
-	if err := mobileconfig.FleetCARootTemplate.Execute(&rootCAProfContents, params); err != nil {
-		return ctxerr.Wrap(ctx, err, "executing fleet root CA config template")
+	if cmd.Command.Identifier == "" {
+		return nil
+	}
+
+	host, err := svc.ds.HostLiteByIdentifier(ctx, cmdResult.UDID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host of RemoveApplication result")
+	}
+	if err := svc.ds.DeleteHostSoftwareByBundleIdentifier(ctx, host.ID, cmd.Command.Identifier); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete removed application from host software")
+	}
+	return nil
+}
+
+// handleActivationLockBypassCodeAck escrows the Activation Lock bypass code
+// reported by the host in response to an ActivationLockBypassCode command.
+func (svc *MDMAppleCheckinAndCommandService) handleActivationLockBypassCodeAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		ActivationLockBypassCode string
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal ActivationLockBypassCode result")
+	}
+	if res.ActivationLockBypassCode == "" {
+		// the code is not available, e.g. Activation Lock is not turned on
+		// yet.
+		level.Debug(svc.logger).Log("msg", "no activation lock bypass code", "host_uuid", cmdResult.UDID)
+		return nil
+	}
+	return ctxerr.Wrap(ctx, svc.ds.SetHostMDMAppleActivationLockBypassCode(ctx, cmdResult.UDID, res.ActivationLockBypassCode),
+		"store host activation lock bypass code")
+}
+
+// handleSetRecoveryLockAck escrows the Recovery Lock password set by a
+// SetRecoveryLock command and sends the VerifyRecoveryLock command to check
+// it.
+func (svc *MDMAppleCheckinAndCommandService) handleSetRecoveryLockAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	pwd, err := svc.ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, "")
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update recovery lock from SetRecoveryLock result")
+	}
+	if pwd == nil || pwd.Password == "" || svc.commander == nil {
+		return nil
+	}
+
+	// record the command before sending it, so that its result finds it.
+	cmdUUID := uuid.NewString()
+	if err := svc.ds.SetHostMDMAppleRecoveryLockPendingVerify(ctx, pwd.HostID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "set recovery lock pending verify")
+	}
+	if err := svc.commander.VerifyRecoveryLock(ctx, []string{cmdResult.UDID}, cmdUUID, pwd.Password); err != nil {
+		var apnsErr *apple_mdm.APNSDeliveryError
+		if !errors.As(err, &apnsErr) {
+			return ctxerr.Wrap(ctx, err, "send VerifyRecoveryLock command")
+		}
+	}
+	return nil
+}
+
+// handleVerifyRecoveryLockAck records whether the host verified its escrowed
+// Recovery Lock password.
+func (svc *MDMAppleCheckinAndCommandService) handleVerifyRecoveryLockAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		PasswordVerified bool
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal VerifyRecoveryLock result")
+	}
+	if !res.PasswordVerified {
+		level.Info(svc.logger).Log("msg", "escrowed recovery lock password failed verification", "host_uuid", cmdResult.UDID)
+	}
+	return ctxerr.Wrap(ctx, svc.ds.UpdateHostMDMAppleRecoveryLockFromVerifyResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, res.PasswordVerified),
+		"update recovery lock from VerifyRecoveryLock result")
+}
+
+// handleSetFirmwarePasswordAck escrows the firmware password set by a
+// SetFirmwarePassword command and sends the VerifyFirmwarePassword command to
+// check it.
+func (svc *MDMAppleCheckinAndCommandService) handleSetFirmwarePasswordAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		SetFirmwarePassword struct {
+			PasswordChanged bool
+		}
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal SetFirmwarePassword result")
+	}
+	if !res.SetFirmwarePassword.PasswordChanged {
+		// e.g. the current password sent with the command didn't match, the
+		// host keeps its current password.
+		_, err := svc.ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, "The firmware password was not changed.")
+		return ctxerr.Wrap(ctx, err, "update firmware password from unchanged SetFirmwarePassword result")
+	}
+
+	pwd, err := svc.ds.UpdateHostMDMAppleFirmwarePasswordFromSetResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, "")
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update firmware password from SetFirmwarePassword result")
+	}
+	if pwd == nil || pwd.Password == "" || svc.commander == nil {
+		return nil
+	}
+
+	// record the command before sending it, so that its result finds it.
+	cmdUUID := uuid.NewString()
+	if err := svc.ds.SetHostMDMAppleFirmwarePasswordPendingVerify(ctx, pwd.HostID, cmdUUID); err != nil {
+		return ctxerr.Wrap(ctx, err, "set firmware password pending verify")
+	}
+	if err := svc.commander.VerifyFirmwarePassword(ctx, []string{cmdResult.UDID}, cmdUUID, pwd.Password); err != nil {
+		var apnsErr *apple_mdm.APNSDeliveryError
+		if !errors.As(err, &apnsErr) {
+			return ctxerr.Wrap(ctx, err, "send VerifyFirmwarePassword command")
+		}
+	}
+	return nil
+}
+
+// handleVerifyFirmwarePasswordAck records whether the host verified its
+// escrowed firmware password.
+func (svc *MDMAppleCheckinAndCommandService) handleVerifyFirmwarePasswordAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		VerifyFirmwarePassword struct {
+			PasswordVerified bool
+		}
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal VerifyFirmwarePassword result")
+	}
+	verified := res.VerifyFirmwarePassword.PasswordVerified
+	if !verified {
+		level.Info(svc.logger).Log("msg", "escrowed firmware password failed verification", "host_uuid", cmdResult.UDID)
+	}
+	return ctxerr.Wrap(ctx, svc.ds.UpdateHostMDMAppleFirmwarePasswordFromVerifyResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, verified),
+		"update firmware password from VerifyFirmwarePassword result")
+}
+
+// handleRotateFileVaultKeyAck re-escrows the new FileVault personal recovery
+// key reported by a host in response to a RotateFileVaultKey command.
+func (svc *MDMAppleCheckinAndCommandService) handleRotateFileVaultKeyAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		RotateResult struct {
+			// EncryptedNewRecoveryKey is encrypted (CMS) with the certificate
+			// sent with the command.
+			EncryptedNewRecoveryKey []byte
+		}
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal RotateFileVaultKey result")
+	}
+
+	// an empty key only clears the pending rotation
+	var base64Key string
+	if len(res.RotateResult.EncryptedNewRecoveryKey) > 0 {
+		base64Key = base64.StdEncoding.EncodeToString(res.RotateResult.EncryptedNewRecoveryKey)
+	}
+	return ctxerr.Wrap(ctx, svc.ds.UpdateHostDiskEncryptionKeyFromRotateResult(ctx, cmdResult.UDID, cmdResult.CommandUUID, base64Key),
+		"update disk encryption key from RotateFileVaultKey result")
+}
+
+// handleDeviceLocationAck stores the location reported by a host in Lost Mode
+// in response to a DeviceLocation command.
+func (svc *MDMAppleCheckinAndCommandService) handleDeviceLocationAck(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	var res struct {
+		Latitude           float64
+		Longitude          float64
+		Altitude           *float64
+		HorizontalAccuracy *float64
+		VerticalAccuracy   *float64
+		Speed              *float64
+		Course             *float64
+		// Timestamp is in ISO 8601 format.
+		Timestamp string
+	}
+	if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal DeviceLocation result")
+	}
+
+	host, err := svc.ds.HostLiteByIdentifier(ctx, cmdResult.UDID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host of DeviceLocation result")
+	}
+
+	loc := &fleet.HostMDMAppleLocation{
+		HostID:             host.ID,
+		CommandUUID:        cmdResult.CommandUUID,
+		Latitude:           res.Latitude,
+		Longitude:          res.Longitude,
+		Altitude:           res.Altitude,
+		HorizontalAccuracy: res.HorizontalAccuracy,
+		VerticalAccuracy:   res.VerticalAccuracy,
+		Speed:              res.Speed,
+		Course:             res.Course,
+	}
+	if res.Timestamp != "" {
+		ts, err := time.Parse(time.RFC3339, res.Timestamp)
+		if err != nil {
+			// the location is still useful without the time it was determined.
+			level.Info(svc.logger).Log("msg", "parse DeviceLocation timestamp", "host_uuid", cmdResult.UDID, "err", err)
+		} else {
+			ts = ts.UTC()
+			loc.ReportedAt = &ts
+		}
+	}
+	return ctxerr.Wrap(ctx, svc.ds.NewHostLocation(ctx, loc), "store host location")
+}
+
+// handleFleetdInstallFailure re-sends the command to install fleetd on the
+// host after a transient failure, up to fleet.MaxFleetdInstallRetries times.
+// The failures of InstallEnterpriseApplication commands that did not install
+// fleetd (e.g. the bootstrap package) are ignored.
+func (svc *MDMAppleCheckinAndCommandService) handleFleetdInstallFailure(ctx context.Context, cmdResult *mdm.CommandResults) error {
+	install, err := svc.ds.GetHostMDMFleetdInstall(ctx, cmdResult.UDID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil
+		}
+		return ctxerr.Wrap(ctx, err, "get host fleetd install")
+	}
+	if install.CommandUUID != cmdResult.CommandUUID {
+		return nil
+	}
+
+	logger := kitlog.With(svc.logger, "host_uuid", cmdResult.UDID, "command_uuid", cmdResult.CommandUUID,
+		"retries", install.Retries, "err", strings.TrimSpace(apple_mdm.FmtErrorChain(cmdResult.ErrorChain)))
+	switch {
+	case cmdResult.Status == fleet.MDMAppleStatusCommandFormatError:
+		// the command itself is invalid, sending it again will not help.
+		level.Error(logger).Log("msg", "fleetd install command format error, not retrying")
+		return nil
+	case install.Retries >= fleet.MaxFleetdInstallRetries:
+		level.Error(logger).Log("msg", "fleetd install failed, giving up after too many retries")
+		return nil
+	}
+
+	level.Info(logger).Log("msg", "fleetd install failed, retrying")
+	err = worker.QueueAppleMDMJob(ctx, svc.ds, svc.logger, worker.AppleMDMRetryFleetdInstallTask, cmdResult.UDID, nil, "")
+	return ctxerr.Wrap(ctx, err, "queue retry fleetd install job")
+}
+
+// recordProfileCertificate tracks the installation of the profiles with a
+// SCEP-backed Wi-Fi or VPN payload, so that they are re-delivered before the
+// certificate issued to the host expires (see RenewAppleProfileCertificates).
+func (svc *MDMAppleCheckinAndCommandService) recordProfileCertificate(ctx context.Context, hostUUID, cmdUUID string) error {
+	prof, err := svc.ds.GetMDMAppleConfigProfileByHostCommandUUID(ctx, hostUUID, cmdUUID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			// not a configuration profile, e.g. the enrollment profile
+			return nil
+		}
+		return ctxerr.Wrap(ctx, err, "get profile installed by command")
+	}
+
+	hasCert, err := prof.Mobileconfig.HasSCEPBackedNetworkPayload()
+	if err != nil {
+		// the profile was accepted by the host, so this is not an error for
+		// the command, it just can't be renewed.
+		level.Info(svc.logger).Log("msg", "parse installed profile for certificates", "profile_uuid", prof.ProfileUUID, "err", err)
+		return nil
+	}
+	if !hasCert {
+		return nil
+	}
+	return ctxerr.Wrap(ctx, svc.ds.RecordHostMDMAppleProfileCertificate(ctx, hostUUID, prof.ProfileUUID),
+		"record host profile certificate")
+}
+
+// deviceInformationResults is the payload of the results of a
+// DeviceInformation command. The fields are nil if the attribute was not
+// queried, so that only the queried details of the host are updated.
+type deviceInformationResults struct {
+	QueryResponses struct {
+		DeviceName              *string
+		DeviceCapacity          *float64
+		AvailableDeviceCapacity *float64
+		OSVersion               *string
+		BuildVersion            *string
+		ProductName             *string
+		ModelName               *string
+		SerialNumber            *string
+		WiFiMAC                 *string
+	}
+}
+
+// securityInfoResults is the payload of the results of a SecurityInfo
+// command. The fields are nil if the attribute was not reported, as some of
+// them depend on the hardware and version of macOS.
+type securityInfoResults struct {
+	SecurityInfo struct {
+		FDEEnabled       *bool `plist:"FDE_Enabled"`
+		FirewallSettings *struct {
+			FirewallEnabled  *bool
+			BlockAllIncoming *bool
+			StealthMode      *bool
+		}
+		SystemIntegrityProtectionEnabled *bool
+		SecureBoot                       *struct {
+			SecureBootLevel *string
+		}
+		RemoteDesktopEnabled *bool
+	}
+}
+
+// hostSecurityInfo returns the security information of the host described by
+// the results.
+func (r securityInfoResults) hostSecurityInfo() *fleet.HostMDMAppleSecurityInfo {
+	info := r.SecurityInfo
+	res := &fleet.HostMDMAppleSecurityInfo{
+		FileVaultEnabled:     info.FDEEnabled,
+		SIPEnabled:           info.SystemIntegrityProtectionEnabled,
+		RemoteDesktopEnabled: info.RemoteDesktopEnabled,
+	}
+	if fw := info.FirewallSettings; fw != nil {
+		res.FirewallEnabled = fw.FirewallEnabled
+		res.FirewallBlockAllIncoming = fw.BlockAllIncoming
+		res.FirewallStealthMode = fw.StealthMode
+	}
+	if sb := info.SecureBoot; sb != nil && sb.SecureBootLevel != nil && *sb.SecureBootLevel != "" {
+		res.SecureBootLevel = sb.SecureBootLevel
+	}
+	return res
+}
+
+// certificateListResults is the payload of the results of a CertificateList
+// command.
+type certificateListResults struct {
+	CertificateList []struct {
+		CommonName string
+		// Data is the DER-encoded certificate.
+		Data []byte
+		// IsIdentity is true if the device has the private key of the
+		// certificate.
+		IsIdentity bool
+	}
+}
+
+// hostCertificates returns the certificates of the host described by the
+// results. The certificates that cannot be parsed are ignored.
+func (r certificateListResults) hostCertificates(hostID uint) []*fleet.HostCertificate {
+	certs := make([]*fleet.HostCertificate, 0, len(r.CertificateList))
+	for _, item := range r.CertificateList {
+		cert, err := x509.ParseCertificate(item.Data)
+		if err != nil {
+			continue
+		}
+		cn := item.CommonName
+		if cn == "" {
+			cn = cert.Subject.CommonName
+		}
+		fingerprint := sha1.Sum(cert.Raw) //nolint:gosec
+		certs = append(certs, &fleet.HostCertificate{
+			HostID:         hostID,
+			SHA1:           hex.EncodeToString(fingerprint[:]),
+			CommonName:     fmt.Sprintf("%.255s", cn),
+			Subject:        fmt.Sprintf("%.1024s", cert.Subject.String()),
+			Issuer:         fmt.Sprintf("%.1024s", cert.Issuer.String()),
+			CA:             cert.IsCA,
+			SelfSigned:     bytes.Equal(cert.RawSubject, cert.RawIssuer),
+			NotValidBefore: cert.NotBefore.UTC(),
+			NotValidAfter:  cert.NotAfter.UTC(),
+			Origin:         fleet.HostCertificateOriginMDM,
+			IsIdentity:     item.IsIdentity,
+		})
+	}
+	return certs
+}
+
+// installedApplicationListResults is the payload of the results of an
+// InstalledApplicationList command.
+type installedApplicationListResults struct {
+	InstalledApplicationList []struct {
+		Identifier   string
+		Name         string
+		ShortVersion string
+		Version      string
+		// Installing is true if the app is being installed or updated.
+		Installing bool
+	}
+}
+
+// software returns the software inventory of the host described by the
+// results. The apps that are being installed are not part of it yet, and an
+// app reported more than once is only listed once.
+func (r installedApplicationListResults) software() []fleet.Software {
+	software := make([]fleet.Software, 0, len(r.InstalledApplicationList))
+	seen := make(map[string]bool, len(r.InstalledApplicationList))
+	for _, app := range r.InstalledApplicationList {
+		if app.Installing {
+			continue
+		}
+		name := strings.TrimSpace(app.Name)
+		if name == "" {
+			// some system apps are reported without a name
+			name = app.Identifier
+		}
+		if name == "" {
+			continue
+		}
+		version := app.ShortVersion
+		if version == "" {
+			version = app.Version
+		}
+		sw := fleet.Software{
+			Name:             name,
+			Version:          version,
+			BundleIdentifier: app.Identifier,
+			Source:           fleet.SoftwareSourceMDM,
+		}
+		if key := sw.ToUniqueStr(); !seen[key] {
+			seen[key] = true
+			software = append(software, sw)
+		}
+	}
+	return software
+}
+
+// ingestRefetchResults updates the details or the security information of the
+// Apple host, or the software of the host not enrolled in osquery, that
+// acknowledged a refetch command (see RefetchIOSAndIPadOSDevices,
+// RefetchAppleMDMSoftware and RefetchHost).
+func (svc *MDMAppleCheckinAndCommandService) ingestRefetchResults(ctx context.Context, requestType string, cmdResult *mdm.CommandResults) error {
+	host, err := svc.ds.HostByIdentifier(ctx, cmdResult.UDID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host to ingest refetch results")
+	}
+
+	switch requestType {
+	case "DeviceInformation":
+		var osName string
+		switch host.Platform {
+		case "ios":
+			osName = "iOS"
+		case "ipados":
+			osName = "iPadOS"
+		case "darwin":
+			osName = "macOS"
+		default:
+			return nil
+		}
+
+		var res deviceInformationResults
+		if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+			return ctxerr.Wrap(ctx, err, "unmarshal DeviceInformation results")
+		}
+		info := res.QueryResponses
+
+		// the details reported by osquery have precedence over the ones
+		// reported by MDM for the hostname, MAC address and operating system of
+		// the Macs running fleetd, only the other details are refreshed.
+		osqueryEnrolled := host.Platform == "darwin" && host.IsOsqueryEnrolled()
+
+		if info.DeviceName != nil {
+			host.ComputerName = *info.DeviceName
+			if !osqueryEnrolled {
+				host.Hostname = *info.DeviceName
+			}
+		}
+		if info.OSVersion != nil {
+			host.OSVersion = strings.TrimSpace(osName + " " + *info.OSVersion)
+		}
+		if info.BuildVersion != nil {
+			host.Build = *info.BuildVersion
+		}
+		if info.ProductName != nil {
+			host.HardwareVendor = "Apple Inc."
+			host.HardwareModel = *info.ProductName
+		}
+		if info.SerialNumber != nil && *info.SerialNumber != "" {
+			host.HardwareSerial = *info.SerialNumber
+		}
+		if info.WiFiMAC != nil && !osqueryEnrolled {
+			host.PrimaryMac = *info.WiFiMAC
+		}
+		if !osqueryEnrolled {
+			host.DetailUpdatedAt = time.Now()
+			host.RefetchRequested = false
+		}
+		if err := svc.ds.UpdateHost(ctx, host); err != nil {
+			return ctxerr.Wrap(ctx, err, "update host from DeviceInformation results")
+		}
+
+		if info.DeviceCapacity != nil && info.AvailableDeviceCapacity != nil && *info.DeviceCapacity > 0 {
+			capacity, available := *info.DeviceCapacity, *info.AvailableDeviceCapacity
+			percentAvailable := available * 100 / capacity
+			if err := svc.ds.SetOrUpdateHostDisksSpace(ctx, host.ID, available, percentAvailable, capacity); err != nil {
+				return ctxerr.Wrap(ctx, err, "update host disk space from DeviceInformation results")
+			}
+		}
+
+		if info.OSVersion != nil && *info.OSVersion != "" && !osqueryEnrolled {
+			if err := svc.ds.UpdateHostOperatingSystem(ctx, host.ID, fleet.OperatingSystem{
+				Name:     osName,
+				Version:  *info.OSVersion,
+				Platform: host.Platform,
+			}); err != nil {
+				return ctxerr.Wrap(ctx, err, "update host operating system from DeviceInformation results")
+			}
+		}
+
+	case "InstalledApplicationList":
+		// if the host enrolled in osquery since the command was sent, the
+		// software reported by osquery has precedence over the one reported by
+		// MDM.
+		var res installedApplicationListResults
+		if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+			return ctxerr.Wrap(ctx, err, "unmarshal InstalledApplicationList results")
+		}
+
+		if _, err := svc.ds.UpdateHostSoftwareFromSource(ctx, host.ID, fleet.SoftwareInventorySourceMDM, res.software()); err != nil {
+			return ctxerr.Wrap(ctx, err, "update host software from InstalledApplicationList results")
+		}
+		// the software is only marked as updated when it changed, mark it as
+		// refreshed anyway so that the host is not refetched again before
+		// RefetchMDMSoftwareInterval.
+		if err := svc.ds.UpdateHostSoftwareUpdatedAt(ctx, host.ID); err != nil {
+			return ctxerr.Wrap(ctx, err, "update host software updated at")
+		}
+
+	case "SecurityInfo":
+		// the attributes of interest are only reported by macOS.
+		if host.Platform != "darwin" {
+			return nil
+		}
+
+		var res securityInfoResults
+		if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+			return ctxerr.Wrap(ctx, err, "unmarshal SecurityInfo results")
+		}
+		if err := svc.ds.SetOrUpdateHostMDMAppleSecurityInfo(ctx, host.ID, res.hostSecurityInfo()); err != nil {
+			return ctxerr.Wrap(ctx, err, "update host security info from SecurityInfo results")
+		}
+
+	case "CertificateList":
+		// only the certificates reported by MDM are replaced, the ones
+		// reported by osquery on the Macs running fleetd are kept.
+		var res certificateListResults
+		if err := plist.Unmarshal(cmdResult.Raw, &res); err != nil {
+			return ctxerr.Wrap(ctx, err, "unmarshal CertificateList results")
+		}
+		if err := svc.ds.ReplaceHostCertificates(ctx, host.ID, fleet.HostCertificateOriginMDM, res.hostCertificates(host.ID)); err != nil {
+			return ctxerr.Wrap(ctx, err, "update host certificates from CertificateList results")
+		}
+	}
+
+	return nil
+}
+
+// mdmAppleDeliveryStatusFromCommandStatus converts a MDM command status to a
+// fleet.MDMAppleDeliveryStatus.
+//
+// NOTE: this mapping does not include all
+// possible delivery statuses (e.g., verified status is not included) is intended to
+// only be used in the context of CommandAndReportResults in the MDMAppleCheckinAndCommandService.
+// Extra care should be taken before using this function in other contexts.
+func mdmAppleDeliveryStatusFromCommandStatus(cmdStatus string) *fleet.MDMDeliveryStatus {
+	switch cmdStatus {
+	case fleet.MDMAppleStatusAcknowledged:
+		return &fleet.MDMDeliveryVerifying
+	case fleet.MDMAppleStatusError, fleet.MDMAppleStatusCommandFormatError:
+		return &fleet.MDMDeliveryFailed
+	case fleet.MDMAppleStatusIdle, fleet.MDMAppleStatusNotNow:
+		return &fleet.MDMDeliveryPending
+	default:
+		return nil
+	}
+}
+
+// ensureFleetProfiles ensures there's a fleetd configuration profile in
+// mdm_apple_configuration_profiles for each team and for "no team"
+//
+// We try our best to use each team's secret but we default to creating a
+// profile with the global enroll secret if the team doesn't have any enroll
+// secrets.
+//
+// This profile will be installed to all hosts in the team (or "no team",) but it
+// will only be used by hosts that have a fleetd installation without an enroll
+// secret and fleet URL (mainly DEP enrolled hosts).
+func ensureFleetProfiles(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, signingCert *tls.Certificate) error {
+	appCfg, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "fetching app config")
+	}
+
+	var rootCAProfContents bytes.Buffer
+	params := mobileconfig.FleetCARootTemplateOptions{
+		PayloadIdentifier: mobileconfig.FleetCARootConfigPayloadIdentifier,
+		PayloadName:       mdm_types.FleetCAConfigProfileName,
+		Certificate:       base64.StdEncoding.EncodeToString(signingCert.Certificate[0]),
+	}
+
+	if err := mobileconfig.FleetCARootTemplate.Execute(&rootCAProfContents, params); err != nil {
+		return ctxerr.Wrap(ctx, err, "executing fleet root CA config template")
 	}
 
 	b := rootCAProfContents.Bytes()
@@ -2784,6 +3869,123 @@ func ensureFleetProfiles(ctx context.Context, ds fleet.Datastore, logger kitlog.
 	return nil
 }
 
+// RefetchIOSAndIPadOSDevices enqueues the MDM command to refetch the details
+// of the iPhones and iPads that are due, as they don't run osquery. The
+// results are ingested by CommandAndReportResults.
+func RefetchIOSAndIPadOSDevices(
+	ctx context.Context,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "reading app config")
+	}
+	if !appConfig.MDM.EnabledAndConfigured {
+		return nil
+	}
+
+	hostUUIDs, err := ds.ListIOSAndIPadOSToRefetch(ctx, fleet.RefetchIOSAndIPadOSInterval)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list iOS and iPadOS devices to refetch")
+	}
+	if len(hostUUIDs) == 0 {
+		return nil
+	}
+
+	// a failure to send the push notifications is not an error, the commands
+	// are enqueued and will be delivered on the next check-in of the devices.
+	var apnsErr *apple_mdm.APNSDeliveryError
+	err = commander.DeviceInformation(ctx, hostUUIDs, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString(), nil)
+	if err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send DeviceInformation commands to refetch devices")
+	}
+	err = commander.CertificateList(ctx, hostUUIDs, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString())
+	if err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send CertificateList commands to refetch devices")
+	}
+
+	level.Info(logger).Log("msg", "sent commands to refetch iOS and iPadOS devices", "host_number", len(hostUUIDs))
+	return nil
+}
+
+// RefetchAppleMDMSoftware enqueues the InstalledApplicationList MDM command
+// to refetch the software of the Apple hosts that are not enrolled in
+// osquery, such as iPhones, iPads and Macs without fleetd. The results are
+// ingested by CommandAndReportResults into the software inventory with the
+// fleet.SoftwareSourceMDM source.
+func RefetchAppleMDMSoftware(
+	ctx context.Context,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "reading app config")
+	}
+	if !appConfig.MDM.EnabledAndConfigured {
+		return nil
+	}
+
+	hostUUIDs, err := ds.ListAppleHostsForMDMSoftwareRefetch(ctx, fleet.RefetchMDMSoftwareInterval)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list Apple hosts for MDM software refetch")
+	}
+	if len(hostUUIDs) == 0 {
+		return nil
+	}
+
+	var apnsErr *apple_mdm.APNSDeliveryError
+	err = commander.InstalledApplicationList(ctx, hostUUIDs, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString())
+	if err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send InstalledApplicationList commands to refetch software")
+	}
+
+	level.Info(logger).Log("msg", "sent commands to refetch software via MDM", "host_number", len(hostUUIDs))
+	return nil
+}
+
+// RetryNotNowInstallApplicationCommands sends push notifications to the
+// devices that responded NotNow to an InstallApplication command, e.g.
+// because they were locked, at most every
+// fleet.RetryNotNowInstallApplicationInterval. The devices don't reliably
+// check in again by themselves when they can install the app, so the command
+// would otherwise stay pending until the next push for another command.
+func RetryNotNowInstallApplicationCommands(
+	ctx context.Context,
+	ds fleet.Datastore,
+	commander *apple_mdm.MDMAppleCommander,
+	logger kitlog.Logger,
+) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "reading app config")
+	}
+	if !appConfig.MDM.EnabledAndConfigured {
+		return nil
+	}
+
+	hostUUIDs, err := ds.ListHostsWithNotNowMDMAppleCommands(ctx, "InstallApplication",
+		fleet.RetryNotNowInstallApplicationInterval, fleet.MaxNotNowInstallApplicationAge)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list hosts with NotNow InstallApplication commands")
+	}
+	if len(hostUUIDs) == 0 {
+		return nil
+	}
+
+	// the devices that could not be notified are retried on the next run.
+	var apnsErr *apple_mdm.APNSDeliveryError
+	if err := commander.SendNotifications(ctx, hostUUIDs); err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send push notifications to retry InstallApplication commands")
+	}
+
+	level.Info(logger).Log("msg", "sent push notifications to retry NotNow InstallApplication commands", "host_number", len(hostUUIDs))
+	return nil
+}
+
 func ReconcileAppleDeclarations(
 	ctx context.Context,
 	ds fleet.Datastore,
@@ -3012,7 +4214,13 @@ func ReconcileAppleProfiles(
 		var err error
 		switch op {
 		case fleet.MDMOperationTypeInstall:
-			err = commander.InstallProfile(ctx, target.hostUUIDs, profileContents[profUUID], target.cmdUUID)
+			// a missing secret variable is reported as a failed delivery so
+			// that it is retried on the next run.
+			var contents string
+			contents, err = expandSecretVariables(ctx, ds, string(profileContents[profUUID]), true)
+			if err == nil {
+				err = commander.InstallProfile(ctx, target.hostUUIDs, mobileconfig.Mobileconfig(contents), target.cmdUUID)
+			}
 		case fleet.MDMOperationTypeRemove:
 			err = commander.RemoveProfile(ctx, target.hostUUIDs, target.profIdent, target.cmdUUID)
 		}
@@ -3139,60 +4347,285 @@ func RenewSCEPCertificates(
 		assocsWithoutRefs = append(assocsWithoutRefs, assoc)
 	}
 
-	// send a single command for all the hosts without references.
-	if len(assocsWithoutRefs) > 0 {
-		profile, err := apple_mdm.GenerateEnrollmentProfileMobileconfig(
-			appConfig.OrgInfo.OrgName,
-			appConfig.ServerSettings.ServerURL,
-			config.MDM.AppleSCEPChallenge,
-			mdmPushCertTopic,
-		)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "generating enrollment profile for hosts without enroll reference")
+	// send a single command for all the hosts without references enrolled
+	// with the same topic, as the hosts of tenants with their own APNs
+	// certificate are enrolled with the topic of that certificate.
+	var topics []string
+	assocsByTopic := map[string][]fleet.SCEPIdentityAssociation{}
+	for _, assoc := range assocsWithoutRefs {
+		topic := scepAssociationTopic(assoc, mdmPushCertTopic)
+		if _, ok := assocsByTopic[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		assocsByTopic[topic] = append(assocsByTopic[topic], assoc)
+	}
+	for _, topic := range topics {
+		if _, err := sendSCEPRenewalProfile(ctx, ds, config, appConfig, commander, topic, assocsByTopic[topic], ""); err != nil {
+			return ctxerr.Wrap(ctx, err, "renewing SCEP certificates of hosts without enroll reference")
+		}
+	}
+
+	// send individual commands for each host with a reference
+	for _, assoc := range assocsWithRefs {
+		if _, err := sendSCEPRenewalProfile(ctx, ds, config, appConfig, commander, scepAssociationTopic(assoc, mdmPushCertTopic),
+			[]fleet.SCEPIdentityAssociation{assoc}, assoc.EnrollReference); err != nil {
+			return ctxerr.Wrap(ctx, err, "renewing SCEP certificates of hosts with enroll reference")
 		}
+	}
+
+	return nil
+}
 
+// scepAssociationTopic returns the APNs topic the host of assoc is enrolled
+// with, or defaultTopic if it is unknown.
+func scepAssociationTopic(assoc fleet.SCEPIdentityAssociation, defaultTopic string) string {
+	if assoc.Topic != "" {
+		return assoc.Topic
+	}
+	return defaultTopic
+}
+
+// sendSCEPRenewalProfile sends the enrollment profile to the hosts of assocs
+// so that they renew their SCEP identity certificate, and tracks the command
+// as the pending renewal of the associations. If enrollReference is not
+// empty, it is added to the enrollment URL of the profile. It returns the
+// UUID of the command.
+func sendSCEPRenewalProfile(
+	ctx context.Context,
+	ds fleet.Datastore,
+	config *config.FleetConfig,
+	appConfig *fleet.AppConfig,
+	commander *apple_mdm.MDMAppleCommander,
+	mdmPushCertTopic string,
+	assocs []fleet.SCEPIdentityAssociation,
+	enrollReference string,
+) (string, error) {
+	enrollURL := appConfig.ServerSettings.ServerURL
+	if enrollReference != "" {
+		var err error
+		if enrollURL, err = apple_mdm.AddEnrollmentRefToFleetURL(enrollURL, enrollReference); err != nil {
+			return "", ctxerr.Wrap(ctx, err, "adding reference to fleet URL")
+		}
+	}
+
+	profile, err := apple_mdm.GenerateEnrollmentProfileMobileconfig(
+		appConfig.OrgInfo.OrgName,
+		enrollURL,
+		config.MDM.AppleSCEPChallenge,
+		mdmPushCertTopic,
+	)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "generating enrollment profile")
+	}
+
+	cmdUUID := uuid.NewString()
+	uuids := make([]string, 0, len(assocs))
+	for _, assoc := range assocs {
+		uuids = append(uuids, assoc.HostUUID)
+	}
+	// the command is enqueued even if the push notification failed, so the
+	// renewal is tracked in that case too.
+	var apnsErr *apple_mdm.APNSDeliveryError
+	sendErr := commander.InstallProfile(ctx, uuids, profile, cmdUUID)
+	if sendErr != nil && !errors.As(sendErr, &apnsErr) {
+		return "", ctxerr.Wrapf(ctx, sendErr, "sending InstallProfile command for hosts %s", uuids)
+	}
+
+	if err := ds.SetCommandForPendingSCEPRenewal(ctx, assocs, cmdUUID); err != nil {
+		return "", ctxerr.Wrap(ctx, err, "setting pending command associations")
+	}
+	if sendErr != nil {
+		return cmdUUID, ctxerr.Wrapf(ctx, sendErr, "sending InstallProfile command for hosts %s", uuids)
+	}
+	return cmdUUID, nil
+}
+
+// profileCertRenewalThresholdDays defines the number of days before the
+// certificate of a SCEP-backed Wi-Fi or VPN profile expires when the profile
+// is re-delivered to the host.
+const profileCertRenewalThresholdDays = 30
+
+// RenewAppleProfileCertificates re-delivers the Wi-Fi and VPN profiles with a
+// SCEP payload whose certificate is about to expire, so that the hosts request
+// a new certificate before the network authentication fails. The expiration is
+// based on the time the profile was installed on the host and the configured
+// validity of the certificates.
+func RenewAppleProfileCertificates(
+	ctx context.Context,
+	logger kitlog.Logger,
+	ds fleet.Datastore,
+	config *config.FleetConfig,
+	commander *apple_mdm.MDMAppleCommander,
+) error {
+	if !config.MDM.IsAppleSCEPSet() {
+		logger.Log("inf", "skipping renewal of macOS profile certificates as MDM is not fully configured")
+		return nil
+	}
+
+	if commander == nil {
+		logger.Log("inf", "skipping renewal of macOS profile certificates as apple_mdm.MDMAppleCommander was not provided")
+		return nil
+	}
+
+	validityDays := config.MDM.AppleProfileCertificateValidityDays
+	if validityDays <= 0 {
+		logger.Log("inf", "skipping renewal of macOS profile certificates as the certificate validity is not set")
+		return nil
+	}
+	renewAfterDays := validityDays - profileCertRenewalThresholdDays
+	if renewAfterDays < validityDays/2 {
+		// short-lived certificates are renewed halfway through their validity
+		renewAfterDays = validityDays / 2
+	}
+
+	installedBefore := time.Now().AddDate(0, 0, -renewAfterDays)
+	certs, err := ds.GetHostMDMAppleProfileCertificatesToRenew(ctx, installedBefore, maxCertsRenewalPerRun)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting host profile certificates to renew")
+	}
+	if len(certs) == 0 {
+		return nil
+	}
+
+	// send a single command per profile to all the hosts that need it
+	certsByProfile := make(map[string][]fleet.HostMDMAppleProfileCertificate)
+	for _, cert := range certs {
+		certsByProfile[cert.ProfileUUID] = append(certsByProfile[cert.ProfileUUID], cert)
+	}
+	profileUUIDs := make([]string, 0, len(certsByProfile))
+	for profUUID := range certsByProfile {
+		profileUUIDs = append(profileUUIDs, profUUID)
+	}
+	profileContents, err := ds.GetMDMAppleProfilesContents(ctx, profileUUIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get profile contents")
+	}
+
+	for profUUID, certs := range certsByProfile {
 		cmdUUID := uuid.NewString()
-		var uuids []string
-		for _, assoc := range assocsWithoutRefs {
-			uuids = append(uuids, assoc.HostUUID)
-			assoc.RenewCommandUUID = cmdUUID
+		hostUUIDs := make([]string, 0, len(certs))
+		hostProfiles := make([]*fleet.MDMAppleBulkUpsertHostProfilePayload, 0, len(certs))
+		for _, cert := range certs {
+			hostUUIDs = append(hostUUIDs, cert.HostUUID)
+			hostProfiles = append(hostProfiles, &fleet.MDMAppleBulkUpsertHostProfilePayload{
+				ProfileUUID:       cert.ProfileUUID,
+				ProfileIdentifier: cert.ProfileIdentifier,
+				ProfileName:       cert.ProfileName,
+				HostUUID:          cert.HostUUID,
+				CommandUUID:       cmdUUID,
+				OperationType:     fleet.MDMOperationTypeInstall,
+				Status:            &fleet.MDMDeliveryPending,
+				Checksum:          cert.Checksum,
+			})
 		}
 
-		if err := commander.InstallProfile(ctx, uuids, profile, cmdUUID); err != nil {
-			return ctxerr.Wrapf(ctx, err, "sending InstallProfile command for hosts %s", assocsWithoutRefs)
+		// update the profiles in the database before sending the command, as
+		// in ReconcileAppleProfiles, so that the response of the host finds them.
+		if err := ds.BulkUpsertMDMAppleHostProfiles(ctx, hostProfiles); err != nil {
+			return ctxerr.Wrap(ctx, err, "updating host profiles")
+		}
+		if err := ds.SetCommandForPendingProfileCertificateRenewal(ctx, certs, cmdUUID); err != nil {
+			return ctxerr.Wrap(ctx, err, "setting pending profile certificate renewals")
 		}
 
-		if err := ds.SetCommandForPendingSCEPRenewal(ctx, assocsWithoutRefs, cmdUUID); err != nil {
-			return ctxerr.Wrap(ctx, err, "setting pending command associations")
+		contents, err := expandSecretVariables(ctx, ds, string(profileContents[profUUID]), true)
+		if err == nil {
+			err = commander.InstallProfile(ctx, hostUUIDs, mobileconfig.Mobileconfig(contents), cmdUUID)
+		}
+		var apnsErr *apple_mdm.APNSDeliveryError
+		switch {
+		case errors.As(err, &apnsErr):
+			level.Debug(logger).Log("err", "sending push notifications, profile renewal still enqueued", "details", err)
+		case err != nil:
+			level.Error(logger).Log("err", "enqueue command to renew profile certificates", "profile_uuid", profUUID, "details", err)
+			// the profile is delivered again by ReconcileAppleProfiles, which
+			// also completes the renewal once the hosts acknowledge it.
+			for _, hp := range hostProfiles {
+				hp.CommandUUID = ""
+				hp.Status = nil
+			}
+			if err := ds.BulkUpsertMDMAppleHostProfiles(ctx, hostProfiles); err != nil {
+				return ctxerr.Wrap(ctx, err, "reverting status of failed profile renewals")
+			}
 		}
 	}
 
-	// send individual commands for each host with a reference
-	for _, assoc := range assocsWithRefs {
-		enrollURL, err := apple_mdm.AddEnrollmentRefToFleetURL(appConfig.ServerSettings.ServerURL, assoc.EnrollReference)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "adding reference to fleet URL")
-		}
+	return nil
+}
+
+// maxRecoveryLockRotationsPerRun is the maximum number of Recovery Lock
+// passwords set per run of the cron job, each host gets its own command.
+const maxRecoveryLockRotationsPerRun = 200
+
+// recoveryLockRetryInterval is the time after which a failed SetRecoveryLock
+// command is sent again.
+const recoveryLockRetryInterval = 24 * time.Hour
 
-		profile, err := apple_mdm.GenerateEnrollmentProfileMobileconfig(
-			appConfig.OrgInfo.OrgName,
-			enrollURL,
-			config.MDM.AppleSCEPChallenge,
-			mdmPushCertTopic,
-		)
+// RotateAppleRecoveryLockPasswords sets a random Recovery Lock password on
+// the Apple silicon Macs enrolled in Fleet MDM that don't have one yet, and
+// rotates the passwords set more than mdm.apple_recovery_lock_rotation_days
+// ago. The new password is escrowed when the host acknowledges the
+// SetRecoveryLock command, then verified with a VerifyRecoveryLock command.
+func RotateAppleRecoveryLockPasswords(
+	ctx context.Context,
+	logger kitlog.Logger,
+	ds fleet.Datastore,
+	config *config.FleetConfig,
+	commander *apple_mdm.MDMAppleCommander,
+) error {
+	rotationDays := config.MDM.AppleRecoveryLockRotationDays
+	if rotationDays <= 0 {
+		return nil
+	}
+	if commander == nil {
+		logger.Log("inf", "skipping rotation of recovery lock passwords as apple_mdm.MDMAppleCommander was not provided")
+		return nil
+	}
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "reading app config")
+	}
+	if !appConfig.MDM.EnabledAndConfigured {
+		return nil
+	}
+
+	now := time.Now()
+	hosts, err := ds.ListHostsForAppleRecoveryLockRotation(ctx, now.AddDate(0, 0, -rotationDays),
+		now.Add(-recoveryLockRetryInterval), maxRecoveryLockRotationsPerRun)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list hosts for recovery lock rotation")
+	}
+
+	for _, host := range hosts {
+		newPassword, err := apple_mdm.GenerateRecoveryLockPassword()
 		if err != nil {
-			return ctxerr.Wrap(ctx, err, "generating enrollment profile for hosts with enroll reference")
+			return ctxerr.Wrap(ctx, err, "generate recovery lock password")
 		}
+
+		// record the pending password before sending the command, so that its
+		// result finds it.
 		cmdUUID := uuid.NewString()
-		if err := commander.InstallProfile(ctx, []string{assoc.HostUUID}, profile, cmdUUID); err != nil {
-			return ctxerr.Wrapf(ctx, err, "sending InstallProfile command for hosts %s", assocsWithRefs)
+		if err := ds.SetHostMDMAppleRecoveryLockPending(ctx, host.HostID, newPassword, cmdUUID); err != nil {
+			return ctxerr.Wrap(ctx, err, "set recovery lock pending")
 		}
-
-		if err := ds.SetCommandForPendingSCEPRenewal(ctx, []fleet.SCEPIdentityAssociation{assoc}, cmdUUID); err != nil {
-			return ctxerr.Wrap(ctx, err, "setting pending command associations")
+		if err := commander.SetRecoveryLock(ctx, []string{host.HostUUID}, cmdUUID, host.Password, newPassword); err != nil {
+			var apnsErr *apple_mdm.APNSDeliveryError
+			if errors.As(err, &apnsErr) {
+				// the command is enqueued, the host gets it on its next check-in.
+				continue
+			}
+			// the command was not enqueued, it is retried after
+			// recoveryLockRetryInterval.
+			level.Error(logger).Log("msg", "failed to send SetRecoveryLock command", "host_uuid", host.HostUUID, "err", err)
+			if _, err := ds.UpdateHostMDMAppleRecoveryLockFromSetResult(ctx, host.HostUUID, cmdUUID, err.Error()); err != nil {
+				return ctxerr.Wrap(ctx, err, "record failed SetRecoveryLock command")
+			}
 		}
 	}
 
+	if len(hosts) > 0 {
+		level.Info(logger).Log("msg", "sent SetRecoveryLock commands", "host_number", len(hosts))
+	}
 	return nil
 }
 
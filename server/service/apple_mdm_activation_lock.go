@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host Activation Lock bypass code
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostActivationLockBypassCodeRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostActivationLockBypassCodeResponse struct {
+	*fleet.HostMDMAppleActivationLockBypassCode
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostActivationLockBypassCodeResponse) error() error { return r.Err }
+
+func getHostActivationLockBypassCodeEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostActivationLockBypassCodeRequest)
+	code, err := svc.GetHostActivationLockBypassCode(ctx, req.HostID)
+	if err != nil {
+		return getHostActivationLockBypassCodeResponse{Err: err}, nil
+	}
+	return getHostActivationLockBypassCodeResponse{HostMDMAppleActivationLockBypassCode: code}, nil
+}
+
+func (svc *Service) GetHostActivationLockBypassCode(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Clear host Activation Lock bypass code
+////////////////////////////////////////////////////////////////////////////////
+
+type clearHostActivationLockBypassCodeRequest struct {
+	HostID uint `url:"id"`
+}
+
+type clearHostActivationLockBypassCodeResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r clearHostActivationLockBypassCodeResponse) Status() int  { return http.StatusNoContent }
+func (r clearHostActivationLockBypassCodeResponse) error() error { return r.Err }
+
+func clearHostActivationLockBypassCodeEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*clearHostActivationLockBypassCodeRequest)
+	if err := svc.ClearHostActivationLockBypassCode(ctx, req.HostID); err != nil {
+		return clearHostActivationLockBypassCodeResponse{Err: err}, nil
+	}
+	return clearHostActivationLockBypassCodeResponse{}, nil
+}
+
+func (svc *Service) ClearHostActivationLockBypassCode(ctx context.Context, hostID uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
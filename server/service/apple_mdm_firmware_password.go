@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Set host firmware password
+////////////////////////////////////////////////////////////////////////////////
+
+type setHostFirmwarePasswordRequest struct {
+	HostID uint `url:"id"`
+}
+
+type setHostFirmwarePasswordResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r setHostFirmwarePasswordResponse) error() error { return r.Err }
+func (r setHostFirmwarePasswordResponse) Status() int  { return http.StatusAccepted }
+
+func setHostFirmwarePasswordEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*setHostFirmwarePasswordRequest)
+	if err := svc.SetHostFirmwarePassword(ctx, req.HostID); err != nil {
+		return setHostFirmwarePasswordResponse{Err: err}, nil
+	}
+	return setHostFirmwarePasswordResponse{}, nil
+}
+
+func (svc *Service) SetHostFirmwarePassword(ctx context.Context, hostID uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host firmware password
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostFirmwarePasswordRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostFirmwarePasswordResponse struct {
+	*fleet.HostMDMAppleFirmwarePassword
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostFirmwarePasswordResponse) error() error { return r.Err }
+
+func getHostFirmwarePasswordEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostFirmwarePasswordRequest)
+	pwd, err := svc.GetHostFirmwarePassword(ctx, req.HostID)
+	if err != nil {
+		return getHostFirmwarePasswordResponse{Err: err}, nil
+	}
+	return getHostFirmwarePasswordResponse{HostMDMAppleFirmwarePassword: pwd}, nil
+}
+
+func (svc *Service) GetHostFirmwarePassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Enable Lost Mode
+////////////////////////////////////////////////////////////////////////////////
+
+type enableHostLostModeRequest struct {
+	HostID uint `json:"-" url:"id"`
+	fleet.HostLostModeSettings
+}
+
+type enableHostLostModeResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r enableHostLostModeResponse) Status() int  { return http.StatusNoContent }
+func (r enableHostLostModeResponse) error() error { return r.Err }
+
+func enableHostLostModeEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*enableHostLostModeRequest)
+	if err := svc.EnableHostLostMode(ctx, req.HostID, req.HostLostModeSettings); err != nil {
+		return enableHostLostModeResponse{Err: err}, nil
+	}
+	return enableHostLostModeResponse{}, nil
+}
+
+func (svc *Service) EnableHostLostMode(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Disable Lost Mode
+////////////////////////////////////////////////////////////////////////////////
+
+type disableHostLostModeRequest struct {
+	HostID uint `url:"id"`
+}
+
+type disableHostLostModeResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r disableHostLostModeResponse) Status() int  { return http.StatusNoContent }
+func (r disableHostLostModeResponse) error() error { return r.Err }
+
+func disableHostLostModeEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*disableHostLostModeRequest)
+	if err := svc.DisableHostLostMode(ctx, req.HostID); err != nil {
+		return disableHostLostModeResponse{Err: err}, nil
+	}
+	return disableHostLostModeResponse{}, nil
+}
+
+func (svc *Service) DisableHostLostMode(ctx context.Context, hostID uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Lost Mode
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostLostModeRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostLostModeResponse struct {
+	*fleet.HostLostMode
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostLostModeResponse) error() error { return r.Err }
+
+func getHostLostModeEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostLostModeRequest)
+	lostMode, err := svc.GetHostLostMode(ctx, req.HostID)
+	if err != nil {
+		return getHostLostModeResponse{Err: err}, nil
+	}
+	return getHostLostModeResponse{HostLostMode: lostMode}, nil
+}
+
+func (svc *Service) GetHostLostMode(ctx context.Context, hostID uint) (*fleet.HostLostMode, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Request host location
+////////////////////////////////////////////////////////////////////////////////
+
+type requestHostLocationRequest struct {
+	HostID uint `url:"id"`
+}
+
+type requestHostLocationResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r requestHostLocationResponse) Status() int  { return http.StatusAccepted }
+func (r requestHostLocationResponse) error() error { return r.Err }
+
+func requestHostLocationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*requestHostLocationRequest)
+	if err := svc.RequestHostLocation(ctx, req.HostID); err != nil {
+		return requestHostLocationResponse{Err: err}, nil
+	}
+	return requestHostLocationResponse{}, nil
+}
+
+func (svc *Service) RequestHostLocation(ctx context.Context, hostID uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host last location
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostLocationRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostLocationResponse struct {
+	*fleet.HostMDMAppleLocation
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostLocationResponse) error() error { return r.Err }
+
+func getHostLocationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostLocationRequest)
+	loc, err := svc.GetHostLastLocation(ctx, req.HostID)
+	if err != nil {
+		return getHostLocationResponse{Err: err}, nil
+	}
+	return getHostLocationResponse{HostMDMAppleLocation: loc}, nil
+}
+
+func (svc *Service) GetHostLastLocation(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host Recovery Lock password
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostRecoveryLockPasswordRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostRecoveryLockPasswordResponse struct {
+	*fleet.HostMDMAppleRecoveryLockPassword
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostRecoveryLockPasswordResponse) error() error { return r.Err }
+
+func getHostRecoveryLockPasswordEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostRecoveryLockPasswordRequest)
+	pwd, err := svc.GetHostRecoveryLockPassword(ctx, req.HostID)
+	if err != nil {
+		return getHostRecoveryLockPasswordResponse{Err: err}, nil
+	}
+	return getHostRecoveryLockPasswordResponse{HostMDMAppleRecoveryLockPassword: pwd}, nil
+}
+
+func (svc *Service) GetHostRecoveryLockPassword(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
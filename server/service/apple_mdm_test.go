@@ -5,11 +5,13 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -41,6 +43,7 @@ import (
 	nanodep_mock "github.com/fleetdm/fleet/v4/server/mock/nanodep"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/fleetdm/fleet/v4/server/worker"
 	kitlog "github.com/go-kit/kit/log"
 	"github.com/google/uuid"
 	"github.com/groob/plist"
@@ -101,6 +104,9 @@ func setupAppleMDMService(t *testing.T, license *fleet.LicenseInfo) (fleet.Servi
 	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
 		return nil, nil
 	}
+	mdmStorage.EnabledEnrollmentIDsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+		return ids, nil
+	}
 	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
 		res := make(map[string]*mdm.Push, len(tokens))
 		for _, t := range tokens {
@@ -573,6 +579,9 @@ func TestMDMAppleConfigProfileAuthz(t *testing.T) {
 	ds.NewMDMAppleConfigProfileFunc = func(ctx context.Context, cp fleet.MDMAppleConfigProfile) (*fleet.MDMAppleConfigProfile, error) {
 		return &cp, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.ListMDMAppleConfigProfilesFunc = func(ctx context.Context, teamID *uint) ([]*fleet.MDMAppleConfigProfile, error) {
 		return nil, nil
 	}
@@ -627,11 +636,11 @@ func TestMDMAppleConfigProfileAuthz(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 			// test authz create new profile (no team)
-			_, err := svc.NewMDMAppleConfigProfile(ctx, 0, bytes.NewReader(mcBytes), nil)
+			_, err := svc.NewMDMAppleConfigProfile(ctx, 0, bytes.NewReader(mcBytes), fleet.MDMProfileLabelTargeting{}, nil)
 			checkShouldFail(err, tt.shouldFailGlobal)
 
 			// test authz create new profile (team 1)
-			_, err = svc.NewMDMAppleConfigProfile(ctx, 1, bytes.NewReader(mcBytes), nil)
+			_, err = svc.NewMDMAppleConfigProfile(ctx, 1, bytes.NewReader(mcBytes), fleet.MDMProfileLabelTargeting{}, nil)
 			checkShouldFail(err, tt.shouldFailTeam)
 
 			// test authz list profiles (no team)
@@ -686,6 +695,9 @@ func TestNewMDMAppleConfigProfile(t *testing.T) {
 		require.Equal(t, mcBytes, []byte(cp.Mobileconfig))
 		return &cp, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(context.Context, *fleet.User, fleet.ActivityDetails) error {
 		return nil
 	}
@@ -693,7 +705,7 @@ func TestNewMDMAppleConfigProfile(t *testing.T) {
 		return nil
 	}
 
-	cp, err := svc.NewMDMAppleConfigProfile(ctx, 0, r, nil)
+	cp, err := svc.NewMDMAppleConfigProfile(ctx, 0, r, fleet.MDMProfileLabelTargeting{}, nil)
 	require.NoError(t, err)
 	require.Equal(t, "Foo", cp.Name)
 	require.Equal(t, "Bar", cp.Identifier)
@@ -768,6 +780,12 @@ func TestHostDetailsMDMProfiles(t *testing.T) {
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 		return &fleet.HostLockWipeStatus{}, nil
 	}
@@ -1199,8 +1217,9 @@ func TestMDMTokenUpdate(t *testing.T) {
 	}
 	uuid, serial, model, wantTeamID := "ABC-DEF-GHI", "XYZABC", "MacBookPro 16,1", uint(12)
 
-	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, hostUUID string) (*fleet.NanoEnrollment, error) {
-		return &fleet.NanoEnrollment{Enabled: true, Type: "Device", TokenUpdateTally: 1}, nil
+	tally := 1
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, id string) (*fleet.NanoEnrollment, error) {
+		return &fleet.NanoEnrollment{Enabled: true, Type: "Device", TokenUpdateTally: tally}, nil
 	}
 
 	ds.GetHostMDMCheckinInfoFunc = func(ct context.Context, hostUUID string) (*fleet.HostMDMCheckinInfo, error) {
@@ -1218,6 +1237,19 @@ func TestMDMTokenUpdate(t *testing.T) {
 		return j, nil
 	}
 
+	var webhookPayloads []map[string]interface{}
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		webhookPayloads = append(webhookPayloads, payload)
+	}))
+	defer webhookSrv.Close()
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{WebhookSettings: fleet.WebhookSettings{
+			MDMEnrollmentWebhook: fleet.MDMEnrollmentWebhookSettings{Enable: true, DestinationURL: webhookSrv.URL},
+		}}, nil
+	}
+
 	err := svc.TokenUpdate(
 		&mdm.Request{Context: ctx, EnrollID: &mdm.EnrollID{ID: uuid}},
 		&mdm.TokenUpdate{
@@ -1231,6 +1263,49 @@ func TestMDMTokenUpdate(t *testing.T) {
 	require.True(t, ds.NewJobFuncInvoked)
 	ds.GetHostMDMCheckinInfoFuncInvoked = false
 	ds.NewJobFuncInvoked = false
+	require.Len(t, webhookPayloads, 1)
+	require.Equal(t, map[string]interface{}{
+		"event":              "enrolled",
+		"host_uuid":          uuid,
+		"host_serial":        serial,
+		"host_display_name":  model,
+		"host_platform":      "",
+		"installed_from_dep": true,
+	}, webhookPayloads[0]["data"])
+	webhookPayloads = nil
+
+	// the next token updates are not new enrollments
+	tally = 2
+	err = svc.TokenUpdate(
+		&mdm.Request{Context: ctx, EnrollID: &mdm.EnrollID{ID: uuid}},
+		&mdm.TokenUpdate{Enrollment: mdm.Enrollment{UDID: uuid}},
+	)
+	require.NoError(t, err)
+	require.Empty(t, webhookPayloads)
+	ds.GetHostMDMCheckinInfoFuncInvoked = false
+
+	// the user channel is established
+	tally = 1
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		activities = append(activities, activity)
+		return nil
+	}
+	userEnrollID := &mdm.EnrollID{Type: mdm.User, ID: uuid + ":USER-ID", ParentID: uuid}
+	err = svc.TokenUpdate(
+		&mdm.Request{Context: ctx, EnrollID: userEnrollID},
+		&mdm.TokenUpdate{Enrollment: mdm.Enrollment{UDID: uuid, UserID: "USER-ID"}},
+	)
+	require.NoError(t, err)
+	require.False(t, ds.NewJobFuncInvoked)
+	require.Equal(t, []fleet.ActivityDetails{
+		&fleet.ActivityTypeMDMUserChannelEstablished{HostSerial: serial, HostDisplayName: model},
+	}, activities)
+	require.Len(t, webhookPayloads, 1)
+	require.Equal(t, "user_channel_established", webhookPayloads[0]["data"].(map[string]interface{})["event"])
+	webhookPayloads = nil
+	ds.GetHostMDMCheckinInfoFuncInvoked = false
 
 	// with enrollment reference
 	err = svc.TokenUpdate(
@@ -1286,7 +1361,38 @@ func TestMDMCheckout(t *testing.T) {
 		return nil
 	}
 
+	var webhookPayloads []map[string]interface{}
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		webhookPayloads = append(webhookPayloads, payload)
+	}))
+	defer webhookSrv.Close()
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{WebhookSettings: fleet.WebhookSettings{
+			MDMEnrollmentWebhook: fleet.MDMEnrollmentWebhookSettings{Enable: true, DestinationURL: webhookSrv.URL},
+		}}, nil
+	}
+
+	// the check out of the user channel is ignored
 	err := svc.CheckOut(
+		&mdm.Request{
+			Context:  ctx,
+			EnrollID: &mdm.EnrollID{Type: mdm.User, ID: uuid + ":USER-ID", ParentID: uuid},
+		},
+		&mdm.CheckOut{
+			Enrollment: mdm.Enrollment{
+				UDID:   uuid,
+				UserID: "USER-ID",
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.False(t, ds.MDMTurnOffFuncInvoked)
+	require.False(t, ds.NewActivityFuncInvoked)
+	require.Empty(t, webhookPayloads)
+
+	err = svc.CheckOut(
 		&mdm.Request{
 			Context:  ctx,
 			EnrollID: &mdm.EnrollID{ID: uuid},
@@ -1301,6 +1407,8 @@ func TestMDMCheckout(t *testing.T) {
 	require.True(t, ds.MDMTurnOffFuncInvoked)
 	require.True(t, ds.GetHostMDMCheckinInfoFuncInvoked)
 	require.True(t, ds.NewActivityFuncInvoked)
+	require.Len(t, webhookPayloads, 1)
+	require.Equal(t, "unenrolled", webhookPayloads[0]["data"].(map[string]interface{})["event"])
 }
 
 func TestMDMCommandAndReportResultsProfileHandling(t *testing.T) {
@@ -1391,6 +1499,10 @@ func TestMDMCommandAndReportResultsProfileHandling(t *testing.T) {
 		t.Run(fmt.Sprintf("%s%s-%d", c.requestType, c.status, i), func(t *testing.T) {
 			ds := new(mock.Store)
 			svc := MDMAppleCheckinAndCommandService{ds: ds}
+			ds.MarkHostMDMSeenFunc = func(ctx context.Context, uuid string, ts time.Time) error {
+				require.Equal(t, hostUUID, uuid)
+				return nil
+			}
 			ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, targetCmd string) (string, error) {
 				require.Equal(t, commandUUID, targetCmd)
 				return c.requestType, nil
@@ -1413,6 +1525,9 @@ func TestMDMCommandAndReportResultsProfileHandling(t *testing.T) {
 				require.ElementsMatch(t, toRetry, []string{profileIdentifier})
 				return nil
 			}
+			ds.GetMDMAppleConfigProfileByHostCommandUUIDFunc = func(ctx context.Context, hostUUID, cmdUUID string) (*fleet.MDMAppleConfigProfile, error) {
+				return nil, newNotFoundError()
+			}
 
 			_, err := svc.CommandAndReportResults(
 				&mdm.Request{Context: ctx},
@@ -1443,6 +1558,418 @@ func TestMDMCommandAndReportResultsProfileHandling(t *testing.T) {
 	}
 }
 
+// scepWiFiProfileForTest returns a profile with a Wi-Fi payload that
+// authenticates with the certificate of its SCEP payload.
+func scepWiFiProfileForTest(identifier string) []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadContent</key>
+	<array>
+		<dict>
+			<key>PayloadType</key>
+			<string>com.apple.security.scep</string>
+			<key>PayloadUUID</key>
+			<string>SCEP-UUID</string>
+		</dict>
+		<dict>
+			<key>PayloadType</key>
+			<string>com.apple.wifi.managed</string>
+			<key>PayloadCertificateUUID</key>
+			<string>SCEP-UUID</string>
+		</dict>
+	</array>
+	<key>PayloadIdentifier</key>
+	<string>%s</string>
+	<key>PayloadDisplayName</key>
+	<string>%s</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+</dict>
+</plist>`, identifier, identifier))
+}
+
+func TestMDMCommandAndReportResultsProfileCertificate(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	profiles := map[string]*fleet.MDMAppleConfigProfile{
+		"cmd-wifi":  {ProfileUUID: "a-wifi", Mobileconfig: scepWiFiProfileForTest("wifi")},
+		"cmd-other": {ProfileUUID: "a-other", Mobileconfig: mcBytesForTest("N1", "I1", "U1")},
+	}
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, targetCmd string) (string, error) {
+		return "InstallProfile", nil
+	}
+	ds.UpdateOrDeleteHostMDMAppleProfileFunc = func(ctx context.Context, profile *fleet.HostMDMAppleProfile) error {
+		return nil
+	}
+	ds.GetHostMDMProfileRetryCountByCommandUUIDFunc = func(ctx context.Context, host *fleet.Host, cmdUUID string) (fleet.HostMDMProfileRetryCount, error) {
+		return fleet.HostMDMProfileRetryCount{Retries: 1}, nil
+	}
+	ds.GetMDMAppleConfigProfileByHostCommandUUIDFunc = func(ctx context.Context, hostUUID, cmdUUID string) (*fleet.MDMAppleConfigProfile, error) {
+		if prof, ok := profiles[cmdUUID]; ok {
+			return prof, nil
+		}
+		return nil, newNotFoundError()
+	}
+	var recorded []string
+	ds.RecordHostMDMAppleProfileCertificateFunc = func(ctx context.Context, hostUUID, profileUUID string) error {
+		require.Equal(t, "host-uuid", hostUUID)
+		recorded = append(recorded, profileUUID)
+		return nil
+	}
+
+	report := func(cmdUUID, status string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "host-uuid"},
+				CommandUUID: cmdUUID,
+				Status:      status,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// only the acknowledged profile with a SCEP-backed Wi-Fi payload is recorded
+	report("cmd-wifi", fleet.MDMAppleStatusError)
+	report("cmd-other", fleet.MDMAppleStatusAcknowledged)
+	report("cmd-unknown", fleet.MDMAppleStatusAcknowledged)
+	require.Empty(t, recorded)
+	report("cmd-wifi", fleet.MDMAppleStatusAcknowledged)
+	require.Equal(t, []string{"a-wifi"}, recorded)
+}
+
+func TestMDMCommandAndReportResultsFleetdInstall(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	install := &fleet.HostMDMFleetdInstall{CommandUUID: "cmd-fleetd", Status: fleet.MDMFleetdInstallFailed}
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, targetCmd string) (string, error) {
+		return "InstallEnterpriseApplication", nil
+	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		require.Equal(t, "host-uuid", hostUUID)
+		return install, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
+	var jobs []*fleet.Job
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		jobs = append(jobs, job)
+		return job, nil
+	}
+
+	report := func(cmdUUID, status string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "host-uuid"},
+				CommandUUID: cmdUUID,
+				Status:      status,
+				ErrorChain:  []mdm.ErrorChain{{ErrorCode: 12, ErrorDomain: "MCInstallationErrorDomain", USEnglishDescription: "Download failed"}},
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// acknowledged commands and failures of other commands (e.g. the bootstrap
+	// package) are ignored
+	report("cmd-fleetd", fleet.MDMAppleStatusAcknowledged)
+	report("cmd-bootstrap", fleet.MDMAppleStatusError)
+	require.Empty(t, jobs)
+
+	// a format error is not retried
+	report("cmd-fleetd", fleet.MDMAppleStatusCommandFormatError)
+	require.Empty(t, jobs)
+
+	// an error is retried
+	report("cmd-fleetd", fleet.MDMAppleStatusError)
+	require.Len(t, jobs, 1)
+	require.Contains(t, string(*jobs[0].Args), string(worker.AppleMDMRetryFleetdInstallTask))
+	require.True(t, jobs[0].NotBefore.After(time.Now()))
+
+	// but only up to the maximum number of retries
+	install.Retries = fleet.MaxFleetdInstallRetries
+	report("cmd-fleetd", fleet.MDMAppleStatusError)
+	require.Len(t, jobs, 1)
+}
+
+func TestMDMCommandAndReportResultsMarksHostSeen(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	var seen []string
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		seen = append(seen, hostUUID)
+		return errors.New("boom")
+	}
+	ds.CleanMacOSMDMLockFunc = func(ctx context.Context, hostUUID string) error {
+		return nil
+	}
+
+	// an Idle check-in marks the host as seen, and a failure to do so does
+	// not fail the request
+	_, err := svc.CommandAndReportResults(
+		&mdm.Request{Context: ctx},
+		&mdm.CommandResults{Enrollment: mdm.Enrollment{UDID: "host-uuid"}, Status: "Idle"},
+	)
+	require.NoError(t, err)
+	require.True(t, ds.CleanMacOSMDMLockFuncInvoked)
+	require.Equal(t, []string{"host-uuid"}, seen)
+}
+
+func TestMDMCommandAndReportResultsRefetchIOS(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	var requestType string
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return requestType, nil
+	}
+	host := &fleet.Host{ID: 1, UUID: "ipad-uuid", Platform: "ipados", RefetchRequested: true}
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		require.Equal(t, host.UUID, identifier)
+		return host, nil
+	}
+	var updated *fleet.Host
+	ds.UpdateHostFunc = func(ctx context.Context, h *fleet.Host) error {
+		updated = h
+		return nil
+	}
+	var gigsAvailable, percentAvailable, gigsTotal float64
+	ds.SetOrUpdateHostDisksSpaceFunc = func(ctx context.Context, hostID uint, gigsAvail, percentAvail, gigsTot float64) error {
+		gigsAvailable, percentAvailable, gigsTotal = gigsAvail, percentAvail, gigsTot
+		return nil
+	}
+	var os fleet.OperatingSystem
+	ds.UpdateHostOperatingSystemFunc = func(ctx context.Context, hostID uint, hostOS fleet.OperatingSystem) error {
+		os = hostOS
+		return nil
+	}
+	var software []fleet.Software
+	var softwareSource fleet.SoftwareInventorySource
+	ds.UpdateHostSoftwareFromSourceFunc = func(ctx context.Context, hostID uint, source fleet.SoftwareInventorySource, sw []fleet.Software) (*fleet.UpdateHostSoftwareDBResult, error) {
+		software = sw
+		softwareSource = source
+		return &fleet.UpdateHostSoftwareDBResult{}, nil
+	}
+	var softwareUpdatedHostIDs []uint
+	ds.UpdateHostSoftwareUpdatedAtFunc = func(ctx context.Context, hostID uint) error {
+		softwareUpdatedHostIDs = append(softwareUpdatedHostIDs, hostID)
+		return nil
+	}
+
+	report := func(reqType, cmdUUID, status, raw string) {
+		requestType = reqType
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: host.UUID},
+				CommandUUID: cmdUUID,
+				Status:      status,
+				Raw:         []byte(raw),
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	deviceInfo := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>REFETCH-1</string>
+	<key>QueryResponses</key>
+	<dict>
+		<key>AvailableDeviceCapacity</key>
+		<real>32</real>
+		<key>BuildVersion</key>
+		<string>21E236</string>
+		<key>DeviceCapacity</key>
+		<real>128</real>
+		<key>DeviceName</key>
+		<string>Anna's iPad</string>
+		<key>OSVersion</key>
+		<string>17.4.1</string>
+		<key>ProductName</key>
+		<string>iPad13,18</string>
+		<key>SerialNumber</key>
+		<string>IPADSERIAL</string>
+		<key>WiFiMAC</key>
+		<string>a1:b2:c3:d4:e5:f6</string>
+	</dict>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>ipad-uuid</string>
+</dict>
+</plist>`
+
+	// results of commands not sent by the refetcher, or not acknowledged,
+	// are ignored
+	report("DeviceInformation", "some-uuid", fleet.MDMAppleStatusAcknowledged, deviceInfo)
+	report("DeviceInformation", "REFETCH-1", fleet.MDMAppleStatusError, deviceInfo)
+	require.False(t, ds.HostByIdentifierFuncInvoked)
+
+	report("DeviceInformation", "REFETCH-1", fleet.MDMAppleStatusAcknowledged, deviceInfo)
+	require.NotNil(t, updated)
+	require.Equal(t, "Anna's iPad", updated.Hostname)
+	require.Equal(t, "Anna's iPad", updated.ComputerName)
+	require.Equal(t, "iPadOS 17.4.1", updated.OSVersion)
+	require.Equal(t, "21E236", updated.Build)
+	require.Equal(t, "iPad13,18", updated.HardwareModel)
+	require.Equal(t, "IPADSERIAL", updated.HardwareSerial)
+	require.Equal(t, "a1:b2:c3:d4:e5:f6", updated.PrimaryMac)
+	require.False(t, updated.RefetchRequested)
+	require.WithinDuration(t, time.Now(), updated.DetailUpdatedAt, time.Minute)
+	require.Equal(t, 32.0, gigsAvailable)
+	require.Equal(t, 25.0, percentAvailable)
+	require.Equal(t, 128.0, gigsTotal)
+	require.Equal(t, fleet.OperatingSystem{Name: "iPadOS", Version: "17.4.1", Platform: "ipados"}, os)
+
+	installedApps := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>REFETCH-2</string>
+	<key>InstalledApplicationList</key>
+	<array>
+		<dict>
+			<key>Identifier</key>
+			<string>com.google.chrome.ios</string>
+			<key>Name</key>
+			<string>Chrome</string>
+			<key>ShortVersion</key>
+			<string>124.0</string>
+			<key>Version</key>
+			<string>124.0.6367.88</string>
+		</dict>
+		<dict>
+			<key>Identifier</key>
+			<string>com.example.app</string>
+			<key>Name</key>
+			<string>Example</string>
+			<key>Version</key>
+			<string>3</string>
+		</dict>
+		<dict>
+			<key>Identifier</key>
+			<string>com.example.app</string>
+			<key>Name</key>
+			<string>Example</string>
+			<key>Version</key>
+			<string>3</string>
+		</dict>
+		<dict>
+			<key>Identifier</key>
+			<string>com.example.installing</string>
+			<key>Name</key>
+			<string>Installing</string>
+			<key>Installing</key>
+			<true/>
+		</dict>
+		<dict>
+			<key>Identifier</key>
+			<string>com.apple.unnamed</string>
+			<key>Name</key>
+			<string> </string>
+			<key>ShortVersion</key>
+			<string>1.0</string>
+		</dict>
+	</array>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>ipad-uuid</string>
+</dict>
+</plist>`
+	report("InstalledApplicationList", "REFETCH-2", fleet.MDMAppleStatusAcknowledged, installedApps)
+	require.Equal(t, []fleet.Software{
+		{Name: "Chrome", Version: "124.0", BundleIdentifier: "com.google.chrome.ios", Source: fleet.SoftwareSourceMDM},
+		{Name: "Example", Version: "3", BundleIdentifier: "com.example.app", Source: fleet.SoftwareSourceMDM},
+		{Name: "com.apple.unnamed", Version: "1.0", BundleIdentifier: "com.apple.unnamed", Source: fleet.SoftwareSourceMDM},
+	}, software)
+	require.Equal(t, []uint{1}, softwareUpdatedHostIDs)
+
+	// all the details of macOS hosts not enrolled in osquery are updated
+	host = &fleet.Host{ID: 2, UUID: "ipad-uuid", Platform: "darwin", RefetchRequested: true}
+	os = fleet.OperatingSystem{}
+	report("DeviceInformation", "REFETCH-3", fleet.MDMAppleStatusAcknowledged, deviceInfo)
+	require.Equal(t, "Anna's iPad", updated.Hostname)
+	require.Equal(t, "macOS 17.4.1", updated.OSVersion)
+	require.Equal(t, "a1:b2:c3:d4:e5:f6", updated.PrimaryMac)
+	require.False(t, updated.RefetchRequested)
+	require.Equal(t, fleet.OperatingSystem{Name: "macOS", Version: "17.4.1", Platform: "darwin"}, os)
+
+	// only the details that osquery does not report better are updated for
+	// the macOS hosts enrolled in osquery, and only the queried ones
+	partialInfo := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>REFETCH-6</string>
+	<key>QueryResponses</key>
+	<dict>
+		<key>DeviceName</key>
+		<string>Anna's MacBook</string>
+		<key>OSVersion</key>
+		<string>14.5</string>
+	</dict>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>ipad-uuid</string>
+</dict>
+</plist>`
+	host = &fleet.Host{
+		ID: 2, UUID: "ipad-uuid", Platform: "darwin", OsqueryHostID: ptr.String("osquery-id"),
+		Hostname: "annas-macbook.local", PrimaryMac: "aa:bb:cc:dd:ee:ff", Build: "23F79",
+		HardwareModel: "MacBookPro18,3", RefetchRequested: true,
+	}
+	os = fleet.OperatingSystem{}
+	ds.SetOrUpdateHostDisksSpaceFuncInvoked = false
+	report("DeviceInformation", "REFETCH-6", fleet.MDMAppleStatusAcknowledged, partialInfo)
+	require.Equal(t, "Anna's MacBook", updated.ComputerName)
+	require.Equal(t, "annas-macbook.local", updated.Hostname)
+	require.Equal(t, "macOS 14.5", updated.OSVersion)
+	require.Equal(t, "23F79", updated.Build)
+	require.Equal(t, "MacBookPro18,3", updated.HardwareModel)
+	require.Equal(t, "aa:bb:cc:dd:ee:ff", updated.PrimaryMac)
+	require.True(t, updated.RefetchRequested)
+	require.False(t, ds.SetOrUpdateHostDisksSpaceFuncInvoked)
+	require.Empty(t, os)
+
+	// but their software is ingested if they are not enrolled in osquery
+	software = nil
+	report("InstalledApplicationList", "REFETCH-4", fleet.MDMAppleStatusAcknowledged, installedApps)
+	require.Len(t, software, 3)
+
+	// and attributed to MDM otherwise, osquery has precedence over it
+	host = &fleet.Host{ID: 2, UUID: "ipad-uuid", Platform: "darwin", NodeKey: ptr.String("node-key")}
+	software = nil
+	report("InstalledApplicationList", "REFETCH-5", fleet.MDMAppleStatusAcknowledged, installedApps)
+	require.Len(t, software, 3)
+	require.Equal(t, fleet.SoftwareInventorySourceMDM, softwareSource)
+}
+
 func TestMDMBatchSetAppleProfiles(t *testing.T) {
 	svc, ctx, ds := setupAppleMDMService(t, &fleet.LicenseInfo{Tier: fleet.TierPremium})
 
@@ -1455,6 +1982,9 @@ func TestMDMBatchSetAppleProfiles(t *testing.T) {
 	ds.BatchSetMDMAppleProfilesFunc = func(ctx context.Context, teamID *uint, profiles []*fleet.MDMAppleConfigProfile) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -1769,6 +2299,9 @@ func TestMDMBatchSetAppleProfilesBoolArgs(t *testing.T) {
 	ds.BatchSetMDMAppleProfilesFunc = func(ctx context.Context, teamID *uint, profiles []*fleet.MDMAppleConfigProfile) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -1806,6 +2339,9 @@ func TestUpdateMDMAppleSettings(t *testing.T) {
 	ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 		return team, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -1960,6 +2496,9 @@ func TestUpdateMDMAppleSetup(t *testing.T) {
 		ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 			return team, nil
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 		ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 			return nil
 		}
@@ -2859,6 +3398,10 @@ func mobileconfigForTestWithContent(outerName, outerIdentifier, innerIdentifier,
 }
 
 func generateCertWithAPNsTopic() ([]byte, []byte, error) {
+	return generateCertWithTopic("com.apple.mgmt.Example")
+}
+
+func generateCertWithTopic(topic string) ([]byte, []byte, error) {
 	// generate a new private key
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -2882,7 +3425,7 @@ func generateCertWithAPNsTopic() ([]byte, []byte, error) {
 			ExtraNames: []pkix.AttributeTypeAndValue{
 				{
 					Type:  oidUID,
-					Value: "com.apple.mgmt.Example",
+					Value: topic,
 				},
 			},
 		},
@@ -3017,6 +3560,36 @@ func TestRenewSCEPCertificatesBranches(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name: "InstallProfile for hostsWithoutRefs per topic",
+			customExpectations: func(t *testing.T, ds *mock.Store, cfg *config.FleetConfig, appleStore *mock.MDMAppleStore, commander *apple_mdm.MDMAppleCommander) {
+				// the hosts of a tenant with its own APNs certificate are
+				// enrolled with its topic
+				ds.GetHostCertAssociationsToExpireFunc = func(ctx context.Context, expiryDays int, limit int) ([]fleet.SCEPIdentityAssociation, error) {
+					return []fleet.SCEPIdentityAssociation{
+						{HostUUID: "hostUUID1"},
+						{HostUUID: "hostUUID2", Topic: "com.apple.mgmt.Tenant"},
+						{HostUUID: "hostUUID3"},
+					}, nil
+				}
+				appleStore.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+					return map[string]error{}, nil
+				}
+				var renewed [][]string
+				ds.SetCommandForPendingSCEPRenewalFunc = func(ctx context.Context, assocs []fleet.SCEPIdentityAssociation, cmdUUID string) error {
+					var uuids []string
+					for _, assoc := range assocs {
+						uuids = append(uuids, assoc.HostUUID)
+					}
+					renewed = append(renewed, uuids)
+					return nil
+				}
+				t.Cleanup(func() {
+					require.Equal(t, [][]string{{"hostUUID1", "hostUUID3"}, {"hostUUID2"}}, renewed)
+				})
+			},
+			expectedError: false,
+		},
 		{
 			name: "InstallProfile for hostsWithRefs",
 			customExpectations: func(t *testing.T, ds *mock.Store, cfg *config.FleetConfig, appleStore *mock.MDMAppleStore, commander *apple_mdm.MDMAppleCommander) {
@@ -3105,3 +3678,1763 @@ func TestRenewSCEPCertificatesBranches(t *testing.T) {
 		})
 	}
 }
+
+func TestRenewAppleProfileCertificates(t *testing.T) {
+	ctx, logger, ds, cfg, appleStorage, commander := setupTest(t)
+
+	appleStorage.RetrievePushInfoFunc = func(ctx context.Context, targets []string) (map[string]*mdm.Push, error) {
+		pushes := make(map[string]*mdm.Push, len(targets))
+		for _, uuid := range targets {
+			pushes[uuid] = &mdm.Push{PushMagic: "magic" + uuid, Token: []byte("token" + uuid), Topic: "topic" + uuid}
+		}
+		return pushes, nil
+	}
+	appleStorage.RetrievePushCertFunc = func(ctx context.Context, topic string) (*tls.Certificate, string, error) {
+		cert, err := tls.LoadX509KeyPair("./testdata/server.pem", "./testdata/server.key")
+		return &cert, "", err
+	}
+	appleStorage.IsPushCertStaleFunc = func(ctx context.Context, topic string, staleToken string) (bool, error) {
+		return false, nil
+	}
+
+	var installedBefore time.Time
+	ds.GetHostMDMAppleProfileCertificatesToRenewFunc = func(ctx context.Context, before time.Time, limit int) ([]fleet.HostMDMAppleProfileCertificate, error) {
+		installedBefore = before
+		return []fleet.HostMDMAppleProfileCertificate{
+			{HostUUID: "host1", ProfileUUID: "a-wifi", ProfileIdentifier: "wifi", ProfileName: "wifi"},
+			{HostUUID: "host2", ProfileUUID: "a-wifi", ProfileIdentifier: "wifi", ProfileName: "wifi"},
+			{HostUUID: "host1", ProfileUUID: "a-vpn", ProfileIdentifier: "vpn", ProfileName: "vpn"},
+		}, nil
+	}
+	ds.GetMDMAppleProfilesContentsFunc = func(ctx context.Context, uuids []string) (map[string]mobileconfig.Mobileconfig, error) {
+		require.ElementsMatch(t, []string{"a-wifi", "a-vpn"}, uuids)
+		return map[string]mobileconfig.Mobileconfig{
+			"a-wifi": scepWiFiProfileForTest("wifi"),
+			"a-vpn":  scepWiFiProfileForTest("vpn"),
+		}, nil
+	}
+	var upserted []*fleet.MDMAppleBulkUpsertHostProfilePayload
+	ds.BulkUpsertMDMAppleHostProfilesFunc = func(ctx context.Context, payload []*fleet.MDMAppleBulkUpsertHostProfilePayload) error {
+		upserted = append(upserted, payload...)
+		return nil
+	}
+	renewCmds := make(map[string][]string)
+	ds.SetCommandForPendingProfileCertificateRenewalFunc = func(ctx context.Context, certs []fleet.HostMDMAppleProfileCertificate, cmdUUID string) error {
+		for _, cert := range certs {
+			renewCmds[cmdUUID] = append(renewCmds[cmdUUID], cert.HostUUID+"/"+cert.ProfileUUID)
+		}
+		return nil
+	}
+	enqueued := make(map[string][]string)
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, "InstallProfile", cmd.Command.RequestType)
+		enqueued[cmd.CommandUUID] = id
+		return map[string]error{}, nil
+	}
+
+	cfg.MDM.AppleProfileCertificateValidityDays = 365
+	err := RenewAppleProfileCertificates(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+
+	// the profiles installed more than 335 days ago are renewed
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -335), installedBefore, time.Minute)
+	require.Len(t, enqueued, 2)
+	require.Len(t, renewCmds, 2)
+	for cmdUUID, hostUUIDs := range enqueued {
+		require.Len(t, renewCmds[cmdUUID], len(hostUUIDs))
+	}
+	require.Len(t, upserted, 3)
+	for _, hp := range upserted {
+		require.Equal(t, &fleet.MDMDeliveryPending, hp.Status)
+		require.Equal(t, fleet.MDMOperationTypeInstall, hp.OperationType)
+		require.Contains(t, enqueued, hp.CommandUUID)
+	}
+
+	// short-lived certificates are renewed halfway through their validity
+	cfg.MDM.AppleProfileCertificateValidityDays = 20
+	err = RenewAppleProfileCertificates(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -10), installedBefore, time.Minute)
+
+	// a failure to enqueue the command reverts the profiles so that they are
+	// delivered again by the reconciliation of profiles
+	upserted = nil
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		return nil, errors.New("enqueue failed")
+	}
+	err = RenewAppleProfileCertificates(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+	require.Len(t, upserted, 6)
+	for _, hp := range upserted[3:] {
+		require.Nil(t, hp.Status)
+		require.Empty(t, hp.CommandUUID)
+	}
+
+	// nothing is renewed without a commander
+	ds.GetHostMDMAppleProfileCertificatesToRenewFuncInvoked = false
+	err = RenewAppleProfileCertificates(ctx, logger, ds, cfg, nil)
+	require.NoError(t, err)
+	require.False(t, ds.GetHostMDMAppleProfileCertificatesToRenewFuncInvoked)
+}
+
+func TestRefetchIOSAndIPadOSDevices(t *testing.T) {
+	ctx, logger, ds, _, mdmStorage, commander := setupTest(t)
+
+	mdmEnabled := false
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: mdmEnabled}}, nil
+	}
+	var hostUUIDs []string
+	ds.ListIOSAndIPadOSToRefetchFunc = func(ctx context.Context, interval time.Duration) ([]string, error) {
+		require.Equal(t, fleet.RefetchIOSAndIPadOSInterval, interval)
+		return hostUUIDs, nil
+	}
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.True(t, strings.HasPrefix(cmd.CommandUUID, fleet.RefetchMDMCommandUUIDPrefix))
+		requestTypes = append(requestTypes, cmd.Command.RequestType)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// MDM disabled, nothing to do
+	require.NoError(t, RefetchIOSAndIPadOSDevices(ctx, ds, commander, logger))
+	require.False(t, ds.ListIOSAndIPadOSToRefetchFuncInvoked)
+
+	// no device due
+	mdmEnabled = true
+	require.NoError(t, RefetchIOSAndIPadOSDevices(ctx, ds, commander, logger))
+	require.True(t, ds.ListIOSAndIPadOSToRefetchFuncInvoked)
+	require.False(t, mdmStorage.EnqueueCommandFuncInvoked)
+
+	hostUUIDs = []string{"iphone-uuid", "ipad-uuid"}
+	require.NoError(t, RefetchIOSAndIPadOSDevices(ctx, ds, commander, logger))
+	require.Equal(t, []string{"DeviceInformation", "CertificateList"}, requestTypes)
+}
+
+func TestRefetchAppleMDMSoftware(t *testing.T) {
+	ctx, logger, ds, _, mdmStorage, commander := setupTest(t)
+
+	mdmEnabled := false
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: mdmEnabled}}, nil
+	}
+	var hostUUIDs []string
+	ds.ListAppleHostsForMDMSoftwareRefetchFunc = func(ctx context.Context, interval time.Duration) ([]string, error) {
+		require.Equal(t, fleet.RefetchMDMSoftwareInterval, interval)
+		return hostUUIDs, nil
+	}
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, hostUUIDs, id)
+		require.True(t, strings.HasPrefix(cmd.CommandUUID, fleet.RefetchMDMCommandUUIDPrefix))
+		requestTypes = append(requestTypes, cmd.Command.RequestType)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// MDM disabled, nothing to do
+	require.NoError(t, RefetchAppleMDMSoftware(ctx, ds, commander, logger))
+	require.False(t, ds.ListAppleHostsForMDMSoftwareRefetchFuncInvoked)
+
+	// no host due
+	mdmEnabled = true
+	require.NoError(t, RefetchAppleMDMSoftware(ctx, ds, commander, logger))
+	require.True(t, ds.ListAppleHostsForMDMSoftwareRefetchFuncInvoked)
+	require.False(t, mdmStorage.EnqueueCommandFuncInvoked)
+
+	hostUUIDs = []string{"iphone-uuid", "mac-uuid"}
+	require.NoError(t, RefetchAppleMDMSoftware(ctx, ds, commander, logger))
+	require.Equal(t, []string{"InstalledApplicationList"}, requestTypes)
+}
+
+func TestRetryNotNowInstallApplicationCommands(t *testing.T) {
+	ctx, logger, ds, _, mdmStorage, commander := setupTest(t)
+
+	mdmEnabled := false
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: mdmEnabled}}, nil
+	}
+	var hostUUIDs []string
+	ds.ListHostsWithNotNowMDMAppleCommandsFunc = func(ctx context.Context, requestType string, retryInterval, maxAge time.Duration) ([]string, error) {
+		require.Equal(t, "InstallApplication", requestType)
+		require.Equal(t, fleet.RetryNotNowInstallApplicationInterval, retryInterval)
+		require.Equal(t, fleet.MaxNotNowInstallApplicationAge, maxAge)
+		return hostUUIDs, nil
+	}
+	var pushed []string
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		pushed = append(pushed, tokens...)
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// MDM disabled, nothing to do
+	require.NoError(t, RetryNotNowInstallApplicationCommands(ctx, ds, commander, logger))
+	require.False(t, ds.ListHostsWithNotNowMDMAppleCommandsFuncInvoked)
+
+	// no device to retry
+	mdmEnabled = true
+	require.NoError(t, RetryNotNowInstallApplicationCommands(ctx, ds, commander, logger))
+	require.True(t, ds.ListHostsWithNotNowMDMAppleCommandsFuncInvoked)
+	require.False(t, mdmStorage.RetrievePushInfoFuncInvoked)
+
+	// the devices are notified, no command is enqueued
+	hostUUIDs = []string{"iphone-uuid", "ipad-uuid"}
+	require.NoError(t, RetryNotNowInstallApplicationCommands(ctx, ds, commander, logger))
+	require.Equal(t, hostUUIDs, pushed)
+	require.False(t, mdmStorage.EnqueueCommandFuncInvoked)
+}
+
+func TestMDMCommandAndReportResultsSecurityInfo(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "SecurityInfo", nil
+	}
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin"}
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		require.Equal(t, host.UUID, identifier)
+		return host, nil
+	}
+	var info *fleet.HostMDMAppleSecurityInfo
+	ds.SetOrUpdateHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint, i *fleet.HostMDMAppleSecurityInfo) error {
+		require.Equal(t, host.ID, hostID)
+		info = i
+		return nil
+	}
+
+	report := func(cmdUUID, status, raw string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: host.UUID},
+				CommandUUID: cmdUUID,
+				Status:      status,
+				Raw:         []byte(raw),
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	securityInfo := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>REFETCH-1</string>
+	<key>SecurityInfo</key>
+	<dict>
+		<key>FDE_Enabled</key>
+		<true/>
+		<key>FDE_HasInstitutionalRecoveryKey</key>
+		<false/>
+		<key>FirewallSettings</key>
+		<dict>
+			<key>BlockAllIncoming</key>
+			<false/>
+			<key>FirewallEnabled</key>
+			<true/>
+			<key>StealthMode</key>
+			<true/>
+		</dict>
+		<key>RemoteDesktopEnabled</key>
+		<false/>
+		<key>SecureBoot</key>
+		<dict>
+			<key>ExternalBootLevel</key>
+			<string>disallowed</string>
+			<key>SecureBootLevel</key>
+			<string>full</string>
+		</dict>
+		<key>SystemIntegrityProtectionEnabled</key>
+		<true/>
+	</dict>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>mac-uuid</string>
+</dict>
+</plist>`
+
+	// not a refetch command
+	report("some-uuid", fleet.MDMAppleStatusAcknowledged, securityInfo)
+	require.False(t, ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked)
+
+	// failed command
+	report(fleet.RefetchMDMCommandUUIDPrefix+"1", fleet.MDMAppleStatusError, securityInfo)
+	require.False(t, ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked)
+
+	report(fleet.RefetchMDMCommandUUIDPrefix+"1", fleet.MDMAppleStatusAcknowledged, securityInfo)
+	require.True(t, ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked)
+	require.Equal(t, &fleet.HostMDMAppleSecurityInfo{
+		FileVaultEnabled:         ptr.Bool(true),
+		FirewallEnabled:          ptr.Bool(true),
+		FirewallBlockAllIncoming: ptr.Bool(false),
+		FirewallStealthMode:      ptr.Bool(true),
+		SIPEnabled:               ptr.Bool(true),
+		SecureBootLevel:          ptr.String("full"),
+		RemoteDesktopEnabled:     ptr.Bool(false),
+	}, info)
+
+	// the attributes not reported, e.g. by an Intel Mac, are nil
+	ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked = false
+	report(fleet.RefetchMDMCommandUUIDPrefix+"2", fleet.MDMAppleStatusAcknowledged, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>REFETCH-2</string>
+	<key>SecurityInfo</key>
+	<dict>
+		<key>FDE_Enabled</key>
+		<false/>
+		<key>SystemIntegrityProtectionEnabled</key>
+		<true/>
+	</dict>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>mac-uuid</string>
+</dict>
+</plist>`)
+	require.True(t, ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked)
+	require.Equal(t, &fleet.HostMDMAppleSecurityInfo{
+		FileVaultEnabled: ptr.Bool(false),
+		SIPEnabled:       ptr.Bool(true),
+	}, info)
+
+	// not ingested for iPhones
+	host.Platform = "ios"
+	ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked = false
+	report(fleet.RefetchMDMCommandUUIDPrefix+"3", fleet.MDMAppleStatusAcknowledged, securityInfo)
+	require.False(t, ds.SetOrUpdateHostMDMAppleSecurityInfoFuncInvoked)
+}
+
+func TestMDMCommandAndReportResultsCertificateList(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "CertificateList", nil
+	}
+	host := &fleet.Host{ID: 1, UUID: "iphone-uuid", Platform: "ios"}
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		require.Equal(t, host.UUID, identifier)
+		return host, nil
+	}
+	var certs []*fleet.HostCertificate
+	ds.ReplaceHostCertificatesFunc = func(ctx context.Context, hostID uint, origin fleet.HostCertificateOrigin, c []*fleet.HostCertificate) error {
+		require.Equal(t, host.ID, hostID)
+		require.Equal(t, fleet.HostCertificateOriginMDM, origin)
+		certs = c
+		return nil
+	}
+
+	// a SCEP CA and an identity it issued
+	caCert, caKey, err := apple_mdm.NewSCEPCACertKey()
+	require.NoError(t, err)
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	notBefore := time.Now().UTC().Truncate(time.Second)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "iphone-identity", Organization: []string{"Fleet"}},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.AddDate(1, 0, 0),
+	}, caCert, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	type certItem struct {
+		CommonName string
+		Data       []byte
+		IsIdentity bool
+	}
+	raw, err := plist.Marshal(struct {
+		CommandUUID     string
+		CertificateList []certItem
+		Status          string
+		UDID            string
+	}{
+		CommandUUID: fleet.RefetchMDMCommandUUIDPrefix + "1",
+		CertificateList: []certItem{
+			{CommonName: "FleetDM", Data: caCert.Raw},
+			{Data: leafDER, IsIdentity: true},
+			// invalid certificates are ignored
+			{CommonName: "invalid", Data: []byte("not a certificate")},
+		},
+		Status: fleet.MDMAppleStatusAcknowledged,
+		UDID:   host.UUID,
+	})
+	require.NoError(t, err)
+
+	report := func(cmdUUID, status string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: host.UUID},
+				CommandUUID: cmdUUID,
+				Status:      status,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// not a refetch command
+	report("some-uuid", fleet.MDMAppleStatusAcknowledged)
+	require.False(t, ds.ReplaceHostCertificatesFuncInvoked)
+
+	// failed command
+	report(fleet.RefetchMDMCommandUUIDPrefix+"1", fleet.MDMAppleStatusError)
+	require.False(t, ds.ReplaceHostCertificatesFuncInvoked)
+
+	report(fleet.RefetchMDMCommandUUIDPrefix+"1", fleet.MDMAppleStatusAcknowledged)
+	require.True(t, ds.ReplaceHostCertificatesFuncInvoked)
+	require.Len(t, certs, 2)
+
+	caSHA1 := sha1.Sum(caCert.Raw) //nolint:gosec
+	require.Equal(t, &fleet.HostCertificate{
+		HostID:         host.ID,
+		SHA1:           hex.EncodeToString(caSHA1[:]),
+		CommonName:     "FleetDM",
+		Subject:        caCert.Subject.String(),
+		Issuer:         caCert.Subject.String(),
+		CA:             true,
+		SelfSigned:     true,
+		NotValidBefore: caCert.NotBefore.UTC(),
+		NotValidAfter:  caCert.NotAfter.UTC(),
+		Origin:         fleet.HostCertificateOriginMDM,
+	}, certs[0])
+
+	// the common name is taken from the certificate if not reported
+	leafSHA1 := sha1.Sum(leafDER) //nolint:gosec
+	require.Equal(t, &fleet.HostCertificate{
+		HostID:         host.ID,
+		SHA1:           hex.EncodeToString(leafSHA1[:]),
+		CommonName:     "iphone-identity",
+		Subject:        "CN=iphone-identity,O=Fleet",
+		Issuer:         caCert.Subject.String(),
+		NotValidBefore: notBefore,
+		NotValidAfter:  notBefore.AddDate(1, 0, 0),
+		Origin:         fleet.HostCertificateOriginMDM,
+		IsIdentity:     true,
+	}, certs[1])
+}
+
+func TestRefetchHostAppleMDM(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+	})
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	mdmEnabled := true
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: mdmEnabled}}, nil
+	}
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.UpdateHostRefetchRequestedFunc = func(ctx context.Context, id uint, value bool) error {
+		return nil
+	}
+	var hostMDM *fleet.HostMDM
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		if hostMDM == nil {
+			return nil, newNotFoundError()
+		}
+		return hostMDM, nil
+	}
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		require.True(t, strings.HasPrefix(cmd.CommandUUID, fleet.RefetchMDMCommandUUIDPrefix))
+		requestTypes = append(requestTypes, cmd.Command.RequestType)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// not enrolled in MDM
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.True(t, ds.GetHostMDMFuncInvoked)
+	require.Empty(t, requestTypes)
+
+	// enrolled in a third-party MDM
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: "Intune"}
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.Empty(t, requestTypes)
+
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.Equal(t, []string{"DeviceInformation", "CertificateList", "SecurityInfo"}, requestTypes)
+
+	// MDM disabled
+	mdmEnabled = false
+	ds.GetHostMDMFuncInvoked = false
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.False(t, ds.GetHostMDMFuncInvoked)
+	require.Len(t, requestTypes, 3)
+
+	// the security information is only refetched for macOS hosts
+	mdmEnabled = true
+	host.Platform = "ios"
+	requestTypes = nil
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.Equal(t, []string{"DeviceInformation", "CertificateList"}, requestTypes)
+
+	// not an Apple host
+	host.Platform = "windows"
+	ds.GetHostMDMFuncInvoked = false
+	require.NoError(t, svc.RefetchHost(ctx, host.ID))
+	require.False(t, ds.GetHostMDMFuncInvoked)
+	require.Len(t, requestTypes, 2)
+}
+
+func TestMDMAppleRestartShutDownDevice(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+	})
+
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin", TeamID: ptr.Uint(1), ComputerName: "mac"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	var hostMDM *fleet.HostMDM
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		if hostMDM == nil {
+			return nil, newNotFoundError()
+		}
+		return hostMDM, nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+	var commands []*mdm.Command
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		commands = append(commands, cmd)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// not authorized
+	_, err = svc.MDMAppleRestartDevice(test.UserContext(ctx, test.UserTeamObserverTeam1), host.ID)
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+	_, err = svc.MDMAppleShutDownDevice(test.UserContext(ctx, test.UserTeamMaintainerTeam2), host.ID)
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+
+	ctx = test.UserContext(ctx, test.UserTeamMaintainerTeam1)
+
+	// not enrolled in MDM, or enrolled in a third-party MDM
+	_, err = svc.MDMAppleRestartDevice(ctx, host.ID)
+	require.ErrorContains(t, err, "Can't restart the host because it doesn't have MDM turned on.")
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: "Intune"}
+	_, err = svc.MDMAppleShutDownDevice(ctx, host.ID)
+	require.ErrorContains(t, err, "Can't shut down the host because it doesn't have MDM turned on.")
+	require.Empty(t, commands)
+
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}
+	restartUUID, err := svc.MDMAppleRestartDevice(ctx, host.ID)
+	require.NoError(t, err)
+	shutDownUUID, err := svc.MDMAppleShutDownDevice(ctx, host.ID)
+	require.NoError(t, err)
+
+	require.Len(t, commands, 2)
+	require.Equal(t, "RestartDevice", commands[0].Command.RequestType)
+	require.Equal(t, restartUUID, commands[0].CommandUUID)
+	require.Equal(t, "ShutDownDevice", commands[1].Command.RequestType)
+	require.Equal(t, shutDownUUID, commands[1].CommandUUID)
+	require.Equal(t, []fleet.ActivityDetails{
+		fleet.ActivityTypeRestartedHost{HostID: host.ID, HostDisplayName: "mac"},
+		fleet.ActivityTypeShutDownHost{HostID: host.ID, HostDisplayName: "mac"},
+	}, activities)
+
+	// not an Apple host
+	host.Platform = "windows"
+	_, err = svc.MDMAppleRestartDevice(ctx, host.ID)
+	require.ErrorContains(t, err, "Can't restart the host because it's not a macOS, iOS or iPadOS host.")
+	require.Len(t, commands, 2)
+}
+
+func TestMDMAppleUnlockUserAccount(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+	})
+
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin", TeamID: ptr.Uint(1), ComputerName: "mac"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}, nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+	var commands []*mdm.Command
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		commands = append(commands, cmd)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+
+	// not authorized
+	_, err = svc.MDMAppleUnlockUserAccount(test.UserContext(ctx, test.UserTeamObserverTeam1), host.ID, "anna")
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+	_, err = svc.MDMAppleUnlockUserAccount(test.UserContext(ctx, test.UserTeamMaintainerTeam2), host.ID, "anna")
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+
+	ctx = test.UserContext(ctx, test.UserTeamMaintainerTeam1)
+
+	_, err = svc.MDMAppleUnlockUserAccount(ctx, host.ID, " ")
+	require.ErrorContains(t, err, "The username of the account to unlock is required.")
+
+	cmdUUID, err := svc.MDMAppleUnlockUserAccount(ctx, host.ID, " anna ")
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	require.Equal(t, "UnlockUserAccount", commands[0].Command.RequestType)
+	require.Equal(t, cmdUUID, commands[0].CommandUUID)
+	require.Contains(t, string(commands[0].Raw), "<key>UserName</key><string>anna</string>")
+	require.Equal(t, []fleet.ActivityDetails{
+		fleet.ActivityTypeUnlockedHostUserAccount{HostID: host.ID, HostDisplayName: "mac", Username: "anna"},
+	}, activities)
+
+	// not a macOS host
+	host.Platform = "ios"
+	_, err = svc.MDMAppleUnlockUserAccount(ctx, host.ID, "anna")
+	require.ErrorContains(t, err, "Can't unlock a user account of the host because it's not a macOS host.")
+	require.Len(t, commands, 1)
+}
+
+func TestMDMAppleRepairHost(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+	})
+
+	host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin", TeamID: ptr.Uint(1), ComputerName: "mac"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	hostMDM := &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return hostMDM, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "fleet"}, ServerSettings: fleet.ServerSettings{ServerURL: "https://foo.example.com"}}, nil
+	}
+	var assoc *fleet.SCEPIdentityAssociation
+	ds.GetHostSCEPIdentityAssociationFunc = func(ctx context.Context, hostUUID string) (*fleet.SCEPIdentityAssociation, error) {
+		require.Equal(t, host.UUID, hostUUID)
+		if assoc == nil {
+			return nil, newNotFoundError()
+		}
+		return assoc, nil
+	}
+	var pendingSCEPRenewals []string
+	ds.SetCommandForPendingSCEPRenewalFunc = func(ctx context.Context, assocs []fleet.SCEPIdentityAssociation, cmdUUID string) error {
+		pendingSCEPRenewals = append(pendingSCEPRenewals, cmdUUID)
+		return nil
+	}
+	var fleetdInstalls []string
+	ds.RecordHostFleetdInstallFunc = func(ctx context.Context, commandUUID string, hostUUID string, retries uint) error {
+		fleetdInstalls = append(fleetdInstalls, commandUUID)
+		return nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
+		require.Equal(t, []uint{host.ID}, hostIDs)
+		return nil
+	}
+	ds.GetHostMDMAppleProfilesFunc = func(ctx context.Context, hostUUID string) ([]fleet.HostMDMAppleProfile, error) {
+		return []fleet.HostMDMAppleProfile{
+			{ProfileUUID: "a1", OperationType: fleet.MDMOperationTypeInstall, Status: &fleet.MDMDeliveryVerified},
+			{ProfileUUID: "a2", OperationType: fleet.MDMOperationTypeInstall, Status: &fleet.MDMDeliveryFailed},
+			{ProfileUUID: "a3", OperationType: fleet.MDMOperationTypeInstall, Status: &fleet.MDMDeliveryPending},
+			{ProfileUUID: "a4", OperationType: fleet.MDMOperationTypeRemove, Status: &fleet.MDMDeliveryVerifying},
+			{ProfileUUID: "d5", OperationType: fleet.MDMOperationTypeInstall, Status: &fleet.MDMDeliveryVerified},
+		}, nil
+	}
+	var resentProfiles []string
+	ds.ResendHostMDMProfileFunc = func(ctx context.Context, hostUUID string, profileUUID string) error {
+		resentProfiles = append(resentProfiles, profileUUID)
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+	var commands []*mdm.Command
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		commands = append(commands, cmd)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+	reset := func() {
+		commands, activities, pendingSCEPRenewals, fleetdInstalls, resentProfiles = nil, nil, nil, nil, nil
+	}
+
+	// not authorized
+	_, err = svc.MDMAppleRepairHost(test.UserContext(ctx, test.UserTeamObserverTeam1), host.ID)
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+	_, err = svc.MDMAppleRepairHost(test.UserContext(ctx, test.UserTeamMaintainerTeam2), host.ID)
+	require.ErrorContains(t, err, authz.ForbiddenErrorMessage)
+
+	ctx = test.UserContext(ctx, test.UserTeamMaintainerTeam1)
+
+	// macOS host with a SCEP identity certificate
+	assoc = &fleet.SCEPIdentityAssociation{HostUUID: host.UUID, SHA256: "sha", EnrollReference: "ref"}
+	repair, err := svc.MDMAppleRepairHost(ctx, host.ID)
+	require.NoError(t, err)
+	require.Len(t, commands, 3)
+	require.Equal(t, "InstallProfile", commands[0].Command.RequestType)
+	require.Equal(t, "InstallEnterpriseApplication", commands[1].Command.RequestType)
+	require.Equal(t, "DeviceInformation", commands[2].Command.RequestType)
+	require.Equal(t, &fleet.MDMAppleHostRepair{
+		SCEPRenewalCommandUUID:       commands[0].CommandUUID,
+		FleetdInstallCommandUUID:     commands[1].CommandUUID,
+		ResentProfiles:               2,
+		DeviceInformationCommandUUID: commands[2].CommandUUID,
+	}, repair)
+	require.Equal(t, []string{commands[0].CommandUUID}, pendingSCEPRenewals)
+	require.Equal(t, []string{commands[1].CommandUUID}, fleetdInstalls)
+	require.Equal(t, []string{"a1", "a2"}, resentProfiles)
+	require.Equal(t, []fleet.ActivityDetails{
+		fleet.ActivityTypeRepairedHostMDM{HostID: host.ID, HostDisplayName: "mac"},
+	}, activities)
+
+	// iOS host without a SCEP identity certificate
+	reset()
+	assoc = nil
+	host.Platform = "ios"
+	repair, err = svc.MDMAppleRepairHost(ctx, host.ID)
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	require.Equal(t, "DeviceInformation", commands[0].Command.RequestType)
+	require.Equal(t, &fleet.MDMAppleHostRepair{
+		ResentProfiles:               2,
+		DeviceInformationCommandUUID: commands[0].CommandUUID,
+	}, repair)
+	require.Empty(t, pendingSCEPRenewals)
+	require.Empty(t, fleetdInstalls)
+	require.Len(t, activities, 1)
+
+	// MDM turned off
+	reset()
+	hostMDM = &fleet.HostMDM{Enrolled: false}
+	_, err = svc.MDMAppleRepairHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Can't repair the MDM enrollment of the host because it doesn't have MDM turned on.")
+	require.Empty(t, commands)
+	require.Empty(t, activities)
+
+	// not an Apple host
+	host.Platform = "windows"
+	_, err = svc.MDMAppleRepairHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Can't repair the MDM enrollment of the host because it's not a macOS, iOS or iPadOS host.")
+}
+
+func TestMDMCommandAndReportResultsRemoveApplication(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "RemoveApplication", nil
+	}
+	cmdUUID := uuid.NewString()
+	cmdPayload, err := plist.Marshal(struct {
+		CommandUUID string
+		Command     struct {
+			RequestType string
+			Identifier  string
+		}
+	}{
+		CommandUUID: cmdUUID,
+		Command: struct {
+			RequestType string
+			Identifier  string
+		}{RequestType: "RemoveApplication", Identifier: "com.example.app"},
+	})
+	require.NoError(t, err)
+	ds.GetMDMAppleCommandResultsFunc = func(ctx context.Context, commandUUID string) ([]*fleet.MDMCommandResult, error) {
+		require.Equal(t, cmdUUID, commandUUID)
+		return []*fleet.MDMCommandResult{
+			{HostUUID: "other-uuid", CommandUUID: cmdUUID, Payload: []byte("not used")},
+			{HostUUID: "iphone-uuid", CommandUUID: cmdUUID, Payload: cmdPayload},
+		}, nil
+	}
+	ds.HostLiteByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.HostLite, error) {
+		require.Equal(t, "iphone-uuid", identifier)
+		return &fleet.HostLite{ID: 1, UUID: identifier}, nil
+	}
+	ds.DeleteHostSoftwareByBundleIdentifierFunc = func(ctx context.Context, hostID uint, bundleIdentifier string) error {
+		require.Equal(t, uint(1), hostID)
+		require.Equal(t, "com.example.app", bundleIdentifier)
+		return nil
+	}
+
+	report := func(status string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "iphone-uuid"},
+				CommandUUID: cmdUUID,
+				Status:      status,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// the app is kept in the inventory until the device removes it
+	report(fleet.MDMAppleStatusNotNow)
+	require.False(t, ds.DeleteHostSoftwareByBundleIdentifierFuncInvoked)
+	report(fleet.MDMAppleStatusError)
+	require.False(t, ds.DeleteHostSoftwareByBundleIdentifierFuncInvoked)
+
+	report(fleet.MDMAppleStatusAcknowledged)
+	require.True(t, ds.DeleteHostSoftwareByBundleIdentifierFuncInvoked)
+}
+
+func TestMDMCommandAndReportResultsLostMode(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	var requestType string
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return requestType, nil
+	}
+	var gotSucceeded bool
+	ds.UpdateHostLostModeFromAppleMDMResultFunc = func(ctx context.Context, hostUUID, cmdUUID, reqType string, succeeded bool) error {
+		require.Equal(t, "iphone-uuid", hostUUID)
+		require.Equal(t, "lost-mode-uuid", cmdUUID)
+		require.Equal(t, requestType, reqType)
+		gotSucceeded = succeeded
+		return nil
+	}
+
+	report := func(status string) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "iphone-uuid"},
+				CommandUUID: "lost-mode-uuid",
+				Status:      status,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	for _, rt := range []string{"EnableLostMode", "DisableLostMode"} {
+		requestType = rt
+		ds.UpdateHostLostModeFromAppleMDMResultFuncInvoked = false
+
+		// the status is not terminal
+		report(fleet.MDMAppleStatusNotNow)
+		require.False(t, ds.UpdateHostLostModeFromAppleMDMResultFuncInvoked)
+
+		report(fleet.MDMAppleStatusError)
+		require.True(t, ds.UpdateHostLostModeFromAppleMDMResultFuncInvoked)
+		require.False(t, gotSucceeded)
+
+		report(fleet.MDMAppleStatusAcknowledged)
+		require.True(t, gotSucceeded)
+	}
+}
+
+func TestMDMCommandAndReportResultsDeviceLocation(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "DeviceLocation", nil
+	}
+	ds.HostLiteByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.HostLite, error) {
+		require.Equal(t, "iphone-uuid", identifier)
+		return &fleet.HostLite{ID: 1, UUID: identifier}, nil
+	}
+	var gotLoc *fleet.HostMDMAppleLocation
+	ds.NewHostLocationFunc = func(ctx context.Context, loc *fleet.HostMDMAppleLocation) error {
+		gotLoc = loc
+		return nil
+	}
+
+	report := func(status string, raw []byte) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "iphone-uuid"},
+				CommandUUID: "location-uuid",
+				Status:      status,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// a device that is not in Lost Mode responds with an error
+	report(fleet.MDMAppleStatusError, nil)
+	require.False(t, ds.NewHostLocationFuncInvoked)
+
+	report(fleet.MDMAppleStatusAcknowledged, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>location-uuid</string>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>iphone-uuid</string>
+	<key>Latitude</key>
+	<real>45.5017</real>
+	<key>Longitude</key>
+	<real>-73.5673</real>
+	<key>HorizontalAccuracy</key>
+	<real>12.5</real>
+	<key>Speed</key>
+	<real>0</real>
+	<key>Timestamp</key>
+	<string>2024-06-12T10:11:12Z</string>
+</dict>
+</plist>`))
+	require.True(t, ds.NewHostLocationFuncInvoked)
+	require.Equal(t, &fleet.HostMDMAppleLocation{
+		HostID:             1,
+		CommandUUID:        "location-uuid",
+		Latitude:           45.5017,
+		Longitude:          -73.5673,
+		HorizontalAccuracy: ptr.Float64(12.5),
+		Speed:              ptr.Float64(0),
+		ReportedAt:         ptr.Time(time.Date(2024, 6, 12, 10, 11, 12, 0, time.UTC)),
+	}, gotLoc)
+}
+
+func TestHostLostMode(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+		License:     &fleet.LicenseInfo{Tier: fleet.TierPremium},
+	})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+	host := &fleet.Host{ID: 1, TeamID: ptr.Uint(1), UUID: "iphone-uuid", Platform: "ios"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}, nil
+	}
+	var lostMode *fleet.HostLostMode
+	ds.GetHostLostModeFunc = func(ctx context.Context, hostID uint) (*fleet.HostLostMode, error) {
+		if lostMode == nil {
+			return nil, newNotFoundError()
+		}
+		return lostMode, nil
+	}
+	ds.NewHostLostModeFunc = func(ctx context.Context, hostID uint, settings fleet.HostLostModeSettings, enableCommandUUID string) error {
+		lostMode = &fleet.HostLostMode{HostID: hostID, Status: fleet.HostLostModeStatusPendingEnable, HostLostModeSettings: settings, EnableCommandUUID: enableCommandUUID}
+		return nil
+	}
+	ds.SetHostLostModePendingDisableFunc = func(ctx context.Context, hostID uint, disableCommandUUID string) error {
+		lostMode.Status = fleet.HostLostModeStatusPendingDisable
+		lostMode.DisableCommandUUID = &disableCommandUUID
+		return nil
+	}
+	ds.GetHostLastLocationFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleLocation, error) {
+		return &fleet.HostMDMAppleLocation{HostID: hostID, Latitude: 45.5, Longitude: -73.5}, nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		requestTypes = append(requestTypes, cmd.Command.RequestType)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		res := make(map[string]*mdm.Push, len(tokens))
+		for _, t := range tokens {
+			res[t] = &mdm.Push{Token: []byte(t)}
+		}
+		return res, nil
+	}
+	mdmStorage.RetrievePushCertFunc = func(ctx context.Context, topic string) (*tls.Certificate, string, error) {
+		cert, err := tls.LoadX509KeyPair("testdata/server.pem", "testdata/server.key")
+		return &cert, "", err
+	}
+	mdmStorage.IsPushCertStaleFunc = func(ctx context.Context, topic string, staleToken string) (bool, error) {
+		return false, nil
+	}
+	settings := fleet.HostLostModeSettings{Message: "Please return", PhoneNumber: "555-0100"}
+
+	// team observers can read the Lost Mode status, but not change it nor
+	// read the location
+	observerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}})
+	err = svc.EnableHostLostMode(observerCtx, host.ID, settings)
+	checkAuthErr(t, true, err)
+	err = svc.DisableHostLostMode(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	err = svc.RequestHostLocation(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	_, err = svc.GetHostLastLocation(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	lm, err := svc.GetHostLostMode(observerCtx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusDisabled, lm.Status)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}})
+
+	// a message or a phone number is required
+	err = svc.EnableHostLostMode(ctx, host.ID, fleet.HostLostModeSettings{Footnote: "reward"})
+	require.ErrorContains(t, err, "Either a message or a phone number is required")
+
+	// the host is not in Lost Mode
+	err = svc.DisableHostLostMode(ctx, host.ID)
+	require.ErrorContains(t, err, "Host is not in Lost Mode.")
+	err = svc.RequestHostLocation(ctx, host.ID)
+	require.ErrorContains(t, err, "not in Lost Mode")
+
+	require.NoError(t, svc.EnableHostLostMode(ctx, host.ID, settings))
+	require.Equal(t, []string{"EnableLostMode"}, requestTypes)
+	require.Equal(t, []string{fleet.ActivityTypeEnabledLostMode{}.ActivityName()}, activities)
+	lm, err = svc.GetHostLostMode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostLostModeStatusPendingEnable, lm.Status)
+	require.Equal(t, settings, lm.HostLostModeSettings)
+
+	// pending enable
+	err = svc.EnableHostLostMode(ctx, host.ID, settings)
+	require.ErrorContains(t, err, "Host has pending Lost Mode request.")
+	err = svc.DisableHostLostMode(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending Lost Mode request.")
+
+	// enabled
+	lostMode.Status = fleet.HostLostModeStatusEnabled
+	err = svc.EnableHostLostMode(ctx, host.ID, settings)
+	var se interface{ Status() int }
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusConflict, se.Status())
+
+	require.NoError(t, svc.RequestHostLocation(ctx, host.ID))
+	require.Equal(t, []string{"EnableLostMode", "DeviceLocation"}, requestTypes)
+	loc, err := svc.GetHostLastLocation(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, 45.5, loc.Latitude)
+
+	require.NoError(t, svc.DisableHostLostMode(ctx, host.ID))
+	require.Equal(t, []string{"EnableLostMode", "DeviceLocation", "DisableLostMode"}, requestTypes)
+	require.Equal(t, fleet.HostLostModeStatusPendingDisable, lostMode.Status)
+	require.Equal(t, []string{fleet.ActivityTypeEnabledLostMode{}.ActivityName(), fleet.ActivityTypeDisabledLostMode{}.ActivityName()}, activities)
+
+	// pending disable
+	err = svc.DisableHostLostMode(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending request to disable Lost Mode.")
+
+	// not enrolled in Fleet's MDM
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return nil, newNotFoundError()
+	}
+	err = svc.EnableHostLostMode(ctx, host.ID, settings)
+	require.ErrorContains(t, err, "doesn't have MDM turned on")
+
+	// unsupported platform
+	host.Platform = "darwin"
+	err = svc.EnableHostLostMode(ctx, host.ID, settings)
+	require.ErrorContains(t, err, "Lost Mode is only available for iOS and iPadOS hosts")
+}
+
+func TestMDMCommandAndReportResultsActivationLockBypassCode(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: kitlog.NewNopLogger()}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	var requestType string
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return requestType, nil
+	}
+	var gotCode string
+	ds.SetHostMDMAppleActivationLockBypassCodeFunc = func(ctx context.Context, hostUUID, bypassCode string) error {
+		require.Equal(t, "iphone-uuid", hostUUID)
+		gotCode = bypassCode
+		return nil
+	}
+	var gotSucceeded bool
+	ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFunc = func(ctx context.Context, hostUUID, cmdUUID string, succeeded bool) error {
+		require.Equal(t, "iphone-uuid", hostUUID)
+		require.Equal(t, "cmd-uuid", cmdUUID)
+		gotSucceeded = succeeded
+		return nil
+	}
+
+	report := func(status string, raw []byte) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "iphone-uuid"},
+				CommandUUID: "cmd-uuid",
+				Status:      status,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+	result := func(code string) []byte {
+		raw, err := plist.Marshal(map[string]string{
+			"CommandUUID":              "cmd-uuid",
+			"Status":                   fleet.MDMAppleStatusAcknowledged,
+			"UDID":                     "iphone-uuid",
+			"ActivationLockBypassCode": code,
+		})
+		require.NoError(t, err)
+		return raw
+	}
+
+	requestType = "ActivationLockBypassCode"
+	report(fleet.MDMAppleStatusError, nil)
+	require.False(t, ds.SetHostMDMAppleActivationLockBypassCodeFuncInvoked)
+
+	// the code is not available yet
+	report(fleet.MDMAppleStatusAcknowledged, result(""))
+	require.False(t, ds.SetHostMDMAppleActivationLockBypassCodeFuncInvoked)
+
+	report(fleet.MDMAppleStatusAcknowledged, result("AAAA-BBBB-CCCC"))
+	require.True(t, ds.SetHostMDMAppleActivationLockBypassCodeFuncInvoked)
+	require.Equal(t, "AAAA-BBBB-CCCC", gotCode)
+
+	requestType = "ClearActivationLockBypassCode"
+	report(fleet.MDMAppleStatusNotNow, nil)
+	require.False(t, ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFuncInvoked)
+	report(fleet.MDMAppleStatusError, nil)
+	require.True(t, ds.UpdateHostMDMAppleActivationLockBypassCodeFromClearResultFuncInvoked)
+	require.False(t, gotSucceeded)
+	report(fleet.MDMAppleStatusAcknowledged, nil)
+	require.True(t, gotSucceeded)
+}
+
+func TestHostActivationLockBypassCode(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	testCertPEM, testKeyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	config.SetTestMDMConfig(t, &cfg, testCertPEM, testKeyPEM, testBMToken, "../../server/service/testdata")
+	mdmStorage := &mock.MDMAppleStore{}
+	pushFactory, _ := newMockAPNSPushProviderFactory()
+	pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		FleetConfig: &cfg,
+		MDMStorage:  mdmStorage,
+		MDMPusher:   pusher,
+		License:     &fleet.LicenseInfo{Tier: fleet.TierPremium},
+	})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+	host := &fleet.Host{ID: 1, TeamID: ptr.Uint(1), UUID: "iphone-uuid", Platform: "ios"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}, nil
+	}
+	var code *fleet.HostMDMAppleActivationLockBypassCode
+	ds.GetHostMDMAppleActivationLockBypassCodeFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleActivationLockBypassCode, error) {
+		if code == nil {
+			return nil, newNotFoundError()
+		}
+		return code, nil
+	}
+	ds.SetHostMDMAppleActivationLockBypassCodePendingClearFunc = func(ctx context.Context, hostID uint, clearCommandUUID string) error {
+		code.ClearCommandUUID = &clearCommandUUID
+		return nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+	var requestTypes []string
+	mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, []string{host.UUID}, id)
+		requestTypes = append(requestTypes, cmd.Command.RequestType)
+		return map[string]error{}, nil
+	}
+	mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+		res := make(map[string]*mdm.Push, len(tokens))
+		for _, t := range tokens {
+			res[t] = &mdm.Push{Token: []byte(t)}
+		}
+		return res, nil
+	}
+	mdmStorage.RetrievePushCertFunc = func(ctx context.Context, topic string) (*tls.Certificate, string, error) {
+		cert, err := tls.LoadX509KeyPair("testdata/server.pem", "testdata/server.key")
+		return &cert, "", err
+	}
+	mdmStorage.IsPushCertStaleFunc = func(ctx context.Context, topic string, staleToken string) (bool, error) {
+		return false, nil
+	}
+
+	// team observers can't read nor clear the bypass code
+	observerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}})
+	_, err = svc.GetHostActivationLockBypassCode(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	err = svc.ClearHostActivationLockBypassCode(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}})
+
+	// no code escrowed
+	_, err = svc.GetHostActivationLockBypassCode(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+	err = svc.ClearHostActivationLockBypassCode(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has no escrowed Activation Lock bypass code.")
+	require.Empty(t, activities)
+
+	code = &fleet.HostMDMAppleActivationLockBypassCode{HostID: host.ID, BypassCode: "AAAA-BBBB-CCCC"}
+	got, err := svc.GetHostActivationLockBypassCode(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "AAAA-BBBB-CCCC", got.BypassCode)
+	require.Equal(t, []string{fleet.ActivityTypeReadHostActivationLockBypassCode{}.ActivityName()}, activities)
+
+	require.NoError(t, svc.ClearHostActivationLockBypassCode(ctx, host.ID))
+	require.Equal(t, []string{"ClearActivationLockBypassCode"}, requestTypes)
+	require.NotNil(t, code.ClearCommandUUID)
+	require.Equal(t, []string{
+		fleet.ActivityTypeReadHostActivationLockBypassCode{}.ActivityName(),
+		fleet.ActivityTypeClearedHostActivationLockBypassCode{}.ActivityName(),
+	}, activities)
+
+	// pending clear
+	err = svc.ClearHostActivationLockBypassCode(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending request to clear the Activation Lock bypass code.")
+
+	// unsupported platform
+	host.Platform = "windows"
+	err = svc.ClearHostActivationLockBypassCode(ctx, host.ID)
+	require.ErrorContains(t, err, "Activation Lock is only available for Apple hosts")
+}
+
+func TestRotateAppleRecoveryLockPasswords(t *testing.T) {
+	ctx, logger, ds, cfg, appleStorage, commander := setupTest(t)
+
+	appleStorage.RetrievePushInfoFunc = func(ctx context.Context, targets []string) (map[string]*mdm.Push, error) {
+		pushes := make(map[string]*mdm.Push, len(targets))
+		for _, uuid := range targets {
+			pushes[uuid] = &mdm.Push{PushMagic: "magic" + uuid, Token: []byte("token" + uuid), Topic: "topic" + uuid}
+		}
+		return pushes, nil
+	}
+	appleStorage.RetrievePushCertFunc = func(ctx context.Context, topic string) (*tls.Certificate, string, error) {
+		cert, err := tls.LoadX509KeyPair("./testdata/server.pem", "./testdata/server.key")
+		return &cert, "", err
+	}
+	appleStorage.IsPushCertStaleFunc = func(ctx context.Context, topic string, staleToken string) (bool, error) {
+		return false, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+
+	var rotateBefore, retryBefore time.Time
+	ds.ListHostsForAppleRecoveryLockRotationFunc = func(ctx context.Context, rotate, retry time.Time, limit int) ([]*fleet.HostMDMAppleRecoveryLockPassword, error) {
+		rotateBefore, retryBefore = rotate, retry
+		return []*fleet.HostMDMAppleRecoveryLockPassword{
+			{HostID: 1, HostUUID: "mac1"},
+			{HostID: 2, HostUUID: "mac2", Password: "current"},
+		}, nil
+	}
+	pending := make(map[uint]string)
+	ds.SetHostMDMAppleRecoveryLockPendingFunc = func(ctx context.Context, hostID uint, newPassword, setCmdUUID string) error {
+		pending[hostID] = newPassword
+		return nil
+	}
+	type setCmd struct {
+		CurrentPassword string
+		NewPassword     string
+	}
+	enqueued := make(map[string]setCmd)
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, "SetRecoveryLock", cmd.Command.RequestType)
+		require.Len(t, id, 1)
+		var full struct {
+			Command setCmd
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		enqueued[id[0]] = full.Command
+		return map[string]error{}, nil
+	}
+
+	// disabled by default
+	err := RotateAppleRecoveryLockPasswords(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+	require.False(t, ds.ListHostsForAppleRecoveryLockRotationFuncInvoked)
+
+	cfg.MDM.AppleRecoveryLockRotationDays = 90
+	err = RotateAppleRecoveryLockPasswords(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -90), rotateBefore, time.Minute)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), retryBefore, time.Minute)
+	require.Len(t, enqueued, 2)
+	require.Equal(t, setCmd{NewPassword: pending[1]}, enqueued["mac1"])
+	require.Equal(t, setCmd{CurrentPassword: "current", NewPassword: pending[2]}, enqueued["mac2"])
+	require.NotEqual(t, pending[1], pending[2])
+
+	// a command that could not be enqueued is recorded as failed
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		return nil, errors.New("enqueue failed")
+	}
+	var failed []string
+	ds.UpdateHostMDMAppleRecoveryLockFromSetResultFunc = func(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+		require.Contains(t, errorMessage, "enqueue failed")
+		failed = append(failed, hostUUID)
+		return nil, nil
+	}
+	err = RotateAppleRecoveryLockPasswords(ctx, logger, ds, cfg, commander)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mac1", "mac2"}, failed)
+}
+
+func TestMDMCommandAndReportResultsRecoveryLock(t *testing.T) {
+	ctx, logger, ds, _, appleStorage, commander := setupTest(t)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: logger, commander: commander}
+
+	appleStorage.RetrievePushInfoFunc = func(ctx context.Context, targets []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	var requestType string
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return requestType, nil
+	}
+	var setResult *fleet.HostMDMAppleRecoveryLockPassword
+	var gotError string
+	ds.UpdateHostMDMAppleRecoveryLockFromSetResultFunc = func(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+		require.Equal(t, "mac-uuid", hostUUID)
+		require.Equal(t, "cmd-uuid", cmdUUID)
+		gotError = errorMessage
+		if errorMessage != "" {
+			return nil, nil
+		}
+		return setResult, nil
+	}
+	var verifyCmdUUID string
+	ds.SetHostMDMAppleRecoveryLockPendingVerifyFunc = func(ctx context.Context, hostID uint, cmdUUID string) error {
+		require.Equal(t, uint(1), hostID)
+		verifyCmdUUID = cmdUUID
+		return nil
+	}
+	var verifiedPassword string
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, "VerifyRecoveryLock", cmd.Command.RequestType)
+		require.Equal(t, []string{"mac-uuid"}, id)
+		require.Equal(t, verifyCmdUUID, cmd.CommandUUID)
+		var full struct {
+			Command struct{ Password string }
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		verifiedPassword = full.Command.Password
+		return map[string]error{}, nil
+	}
+	var gotVerified *bool
+	ds.UpdateHostMDMAppleRecoveryLockFromVerifyResultFunc = func(ctx context.Context, hostUUID, cmdUUID string, verified bool) error {
+		require.Equal(t, "mac-uuid", hostUUID)
+		gotVerified = &verified
+		return nil
+	}
+
+	report := func(status string, raw []byte, chain []mdm.ErrorChain) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "mac-uuid"},
+				CommandUUID: "cmd-uuid",
+				Status:      status,
+				ErrorChain:  chain,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	requestType = "SetRecoveryLock"
+	report(fleet.MDMAppleStatusNotNow, nil, nil)
+	require.False(t, ds.UpdateHostMDMAppleRecoveryLockFromSetResultFuncInvoked)
+	report(fleet.MDMAppleStatusError, nil, []mdm.ErrorChain{{ErrorCode: 12345, ErrorDomain: "MCMDMErrorDomain", USEnglishDescription: "Unsupported"}})
+	require.Contains(t, gotError, "Unsupported")
+	require.False(t, appleStorage.EnqueueCommandFuncInvoked)
+
+	// the result of an unknown command doesn't send the verification
+	report(fleet.MDMAppleStatusAcknowledged, nil, nil)
+	require.Empty(t, gotError)
+	require.False(t, appleStorage.EnqueueCommandFuncInvoked)
+
+	setResult = &fleet.HostMDMAppleRecoveryLockPassword{HostID: 1, HostUUID: "mac-uuid", Password: "new-password"}
+	report(fleet.MDMAppleStatusAcknowledged, nil, nil)
+	require.True(t, appleStorage.EnqueueCommandFuncInvoked)
+	require.Equal(t, "new-password", verifiedPassword)
+
+	requestType = "VerifyRecoveryLock"
+	result := func(verified bool) []byte {
+		raw, err := plist.Marshal(map[string]any{
+			"CommandUUID":      "cmd-uuid",
+			"Status":           fleet.MDMAppleStatusAcknowledged,
+			"UDID":             "mac-uuid",
+			"PasswordVerified": verified,
+		})
+		require.NoError(t, err)
+		return raw
+	}
+	report(fleet.MDMAppleStatusError, nil, nil)
+	require.Nil(t, gotVerified)
+	report(fleet.MDMAppleStatusAcknowledged, result(false), nil)
+	require.Equal(t, ptr.Bool(false), gotVerified)
+	report(fleet.MDMAppleStatusAcknowledged, result(true), nil)
+	require.Equal(t, ptr.Bool(true), gotVerified)
+}
+
+func TestMDMCommandAndReportResultsFirmwarePassword(t *testing.T) {
+	ctx, logger, ds, _, appleStorage, commander := setupTest(t)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: logger, commander: commander}
+
+	appleStorage.RetrievePushInfoFunc = func(ctx context.Context, targets []string) (map[string]*mdm.Push, error) {
+		return map[string]*mdm.Push{}, nil
+	}
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	var requestType string
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return requestType, nil
+	}
+	var setResult *fleet.HostMDMAppleFirmwarePassword
+	var gotError string
+	ds.UpdateHostMDMAppleFirmwarePasswordFromSetResultFunc = func(ctx context.Context, hostUUID, cmdUUID, errorMessage string) (*fleet.HostMDMAppleFirmwarePassword, error) {
+		require.Equal(t, "mac-uuid", hostUUID)
+		require.Equal(t, "cmd-uuid", cmdUUID)
+		gotError = errorMessage
+		if errorMessage != "" {
+			return nil, nil
+		}
+		return setResult, nil
+	}
+	var verifyCmdUUID string
+	ds.SetHostMDMAppleFirmwarePasswordPendingVerifyFunc = func(ctx context.Context, hostID uint, cmdUUID string) error {
+		require.Equal(t, uint(1), hostID)
+		verifyCmdUUID = cmdUUID
+		return nil
+	}
+	var verifiedPassword string
+	appleStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+		require.Equal(t, "VerifyFirmwarePassword", cmd.Command.RequestType)
+		require.Equal(t, []string{"mac-uuid"}, id)
+		require.Equal(t, verifyCmdUUID, cmd.CommandUUID)
+		var full struct {
+			Command struct{ Password string }
+		}
+		require.NoError(t, plist.Unmarshal(cmd.Raw, &full))
+		verifiedPassword = full.Command.Password
+		return map[string]error{}, nil
+	}
+	var gotVerified *bool
+	ds.UpdateHostMDMAppleFirmwarePasswordFromVerifyResultFunc = func(ctx context.Context, hostUUID, cmdUUID string, verified bool) error {
+		require.Equal(t, "mac-uuid", hostUUID)
+		gotVerified = &verified
+		return nil
+	}
+
+	report := func(status string, raw []byte, chain []mdm.ErrorChain) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "mac-uuid"},
+				CommandUUID: "cmd-uuid",
+				Status:      status,
+				ErrorChain:  chain,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+	result := func(key, field string, value bool) []byte {
+		raw, err := plist.Marshal(map[string]any{
+			"CommandUUID": "cmd-uuid",
+			"Status":      fleet.MDMAppleStatusAcknowledged,
+			"UDID":        "mac-uuid",
+			key:           map[string]any{field: value},
+		})
+		require.NoError(t, err)
+		return raw
+	}
+
+	requestType = "SetFirmwarePassword"
+	report(fleet.MDMAppleStatusNotNow, nil, nil)
+	require.False(t, ds.UpdateHostMDMAppleFirmwarePasswordFromSetResultFuncInvoked)
+	report(fleet.MDMAppleStatusError, nil, []mdm.ErrorChain{{ErrorCode: 12345, ErrorDomain: "MCMDMErrorDomain", USEnglishDescription: "Unsupported"}})
+	require.Contains(t, gotError, "Unsupported")
+	require.False(t, appleStorage.EnqueueCommandFuncInvoked)
+
+	// the host didn't change its password
+	report(fleet.MDMAppleStatusAcknowledged, result("SetFirmwarePassword", "PasswordChanged", false), nil)
+	require.Equal(t, "The firmware password was not changed.", gotError)
+	require.False(t, appleStorage.EnqueueCommandFuncInvoked)
+
+	// the result of an unknown command doesn't send the verification
+	report(fleet.MDMAppleStatusAcknowledged, result("SetFirmwarePassword", "PasswordChanged", true), nil)
+	require.Empty(t, gotError)
+	require.False(t, appleStorage.EnqueueCommandFuncInvoked)
+
+	setResult = &fleet.HostMDMAppleFirmwarePassword{HostID: 1, HostUUID: "mac-uuid", Password: "K7Q2-XH4N-BW9C"}
+	report(fleet.MDMAppleStatusAcknowledged, result("SetFirmwarePassword", "PasswordChanged", true), nil)
+	require.True(t, appleStorage.EnqueueCommandFuncInvoked)
+	require.Equal(t, "K7Q2-XH4N-BW9C", verifiedPassword)
+
+	requestType = "VerifyFirmwarePassword"
+	report(fleet.MDMAppleStatusError, nil, nil)
+	require.Nil(t, gotVerified)
+	report(fleet.MDMAppleStatusAcknowledged, result("VerifyFirmwarePassword", "PasswordVerified", false), nil)
+	require.Equal(t, ptr.Bool(false), gotVerified)
+	report(fleet.MDMAppleStatusAcknowledged, result("VerifyFirmwarePassword", "PasswordVerified", true), nil)
+	require.Equal(t, ptr.Bool(true), gotVerified)
+}
+
+func TestMDMCommandAndReportResultsRotateFileVaultKey(t *testing.T) {
+	ctx, logger, ds, _, _, commander := setupTest(t)
+	svc := MDMAppleCheckinAndCommandService{ds: ds, logger: logger, commander: commander}
+
+	ds.MarkHostMDMSeenFunc = func(ctx context.Context, hostUUID string, ts time.Time) error {
+		return nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "RotateFileVaultKey", nil
+	}
+	var gotKey *string
+	ds.UpdateHostDiskEncryptionKeyFromRotateResultFunc = func(ctx context.Context, hostUUID, cmdUUID, encryptedBase64Key string) error {
+		require.Equal(t, "mac-uuid", hostUUID)
+		require.Equal(t, "cmd-uuid", cmdUUID)
+		gotKey = &encryptedBase64Key
+		return nil
+	}
+
+	report := func(status string, raw []byte) {
+		_, err := svc.CommandAndReportResults(
+			&mdm.Request{Context: ctx},
+			&mdm.CommandResults{
+				Enrollment:  mdm.Enrollment{UDID: "mac-uuid"},
+				CommandUUID: "cmd-uuid",
+				Status:      status,
+				Raw:         raw,
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	report(fleet.MDMAppleStatusNotNow, nil)
+	require.Nil(t, gotKey)
+
+	// the pending rotation is cleared on error
+	report(fleet.MDMAppleStatusError, nil)
+	require.Equal(t, ptr.String(""), gotKey)
+
+	raw, err := plist.Marshal(map[string]any{
+		"CommandUUID":  "cmd-uuid",
+		"Status":       fleet.MDMAppleStatusAcknowledged,
+		"UDID":         "mac-uuid",
+		"RotateResult": map[string]any{"EncryptedNewRecoveryKey": []byte("encrypted-key")},
+	})
+	require.NoError(t, err)
+	report(fleet.MDMAppleStatusAcknowledged, raw)
+	require.Equal(t, ptr.String(base64.StdEncoding.EncodeToString([]byte("encrypted-key"))), gotKey)
+}
+
+func TestHostFirmwarePassword(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	host := &fleet.Host{ID: 1, TeamID: ptr.Uint(1), UUID: "mac-uuid", Platform: "darwin", CPUType: "arm64e"}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	var pwd *fleet.HostMDMAppleFirmwarePassword
+	ds.GetHostMDMAppleFirmwarePasswordFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleFirmwarePassword, error) {
+		if pwd == nil {
+			return nil, newNotFoundError()
+		}
+		return pwd, nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+
+	// team observers can't set nor read the password
+	observerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}})
+	err := svc.SetHostFirmwarePassword(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	_, err = svc.GetHostFirmwarePassword(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}})
+
+	// Apple silicon Macs and other platforms have no firmware password
+	err = svc.SetHostFirmwarePassword(ctx, host.ID)
+	require.ErrorContains(t, err, "only available for Intel Macs")
+	host.Platform, host.CPUType = "windows", "x86_64"
+	err = svc.SetHostFirmwarePassword(ctx, host.ID)
+	require.ErrorContains(t, err, "only available for Intel Macs")
+	require.False(t, ds.GetHostMDMAppleFirmwarePasswordFuncInvoked)
+
+	_, err = svc.GetHostFirmwarePassword(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the first password is pending
+	pwd = &fleet.HostMDMAppleFirmwarePassword{HostID: host.ID, SetCommandUUID: ptr.String("set-cmd")}
+	_, err = svc.GetHostFirmwarePassword(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.Empty(t, activities)
+
+	pwd = &fleet.HostMDMAppleFirmwarePassword{HostID: host.ID, Password: "K7Q2-XH4N-BW9C", Verified: ptr.Bool(true)}
+	got, err := svc.GetHostFirmwarePassword(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "K7Q2-XH4N-BW9C", got.Password)
+	require.Equal(t, []string{fleet.ActivityTypeReadHostFirmwarePassword{}.ActivityName()}, activities)
+}
+
+func TestHostRecoveryLockPassword(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	host := &fleet.Host{ID: 1, TeamID: ptr.Uint(1), UUID: "mac-uuid", Platform: "darwin"}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	var pwd *fleet.HostMDMAppleRecoveryLockPassword
+	ds.GetHostMDMAppleRecoveryLockPasswordFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleRecoveryLockPassword, error) {
+		if pwd == nil {
+			return nil, newNotFoundError()
+		}
+		return pwd, nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+
+	// team observers can't read the password
+	observerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}})
+	_, err := svc.GetHostRecoveryLockPassword(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}})
+
+	_, err = svc.GetHostRecoveryLockPassword(ctx, host.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.Empty(t, activities)
+
+	pwd = &fleet.HostMDMAppleRecoveryLockPassword{HostID: host.ID, Password: "ABCDE-FGHJK-LMNPQ-RSTUV", Verified: ptr.Bool(true)}
+	got, err := svc.GetHostRecoveryLockPassword(ctx, host.ID)
+	require.NoError(t, err)
+	require.Equal(t, "ABCDE-FGHJK-LMNPQ-RSTUV", got.Password)
+	require.Equal(t, []string{fleet.ActivityTypeReadHostRecoveryLockPassword{}.ActivityName()}, activities)
+}
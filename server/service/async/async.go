@@ -23,6 +23,9 @@ type Task struct {
 	clock       clock.Clock
 	taskConfigs map[config.AsyncTaskName]config.AsyncProcessingConfig
 	seenHostSet seenHostSet
+	// channelSeenHostSets holds the hosts seen by each non-osquery channel,
+	// they are always stored in memory until FlushHostsLastSeen is called.
+	channelSeenHostSets map[fleet.HostSeenChannel]*seenHostSet
 }
 
 // NewTask configures and returns a Task.
@@ -32,11 +35,16 @@ func NewTask(ds fleet.Datastore, pool fleet.RedisPool, clck clock.Clock, conf co
 	taskCfgs[config.AsyncTaskPolicyMembership] = conf.AsyncConfigForTask(config.AsyncTaskPolicyMembership)
 	taskCfgs[config.AsyncTaskHostLastSeen] = conf.AsyncConfigForTask(config.AsyncTaskHostLastSeen)
 	taskCfgs[config.AsyncTaskScheduledQueryStats] = conf.AsyncConfigForTask(config.AsyncTaskScheduledQueryStats)
+	channelSets := make(map[fleet.HostSeenChannel]*seenHostSet, len(fleet.HostSeenChannels))
+	for _, c := range fleet.HostSeenChannels {
+		channelSets[c] = &seenHostSet{}
+	}
 	return &Task{
-		datastore:   ds,
-		pool:        pool,
-		clock:       clck,
-		taskConfigs: taskCfgs,
+		datastore:           ds,
+		pool:                pool,
+		clock:               clck,
+		taskConfigs:         taskCfgs,
+		channelSeenHostSets: channelSets,
 	}
 }
 
@@ -58,11 +58,35 @@ func (t *Task) RecordHostLastSeen(ctx context.Context, hostID uint) error {
 	return nil
 }
 
+// RecordHostSeenByChannel records that the specified host ID was seen by the
+// channel (e.g. orbit or Fleet Desktop). Those are always stored in memory
+// until FlushHostsLastSeen is called, regardless of the asynchronous
+// processing configuration.
+func (t *Task) RecordHostSeenByChannel(ctx context.Context, hostID uint, channel fleet.HostSeenChannel) error {
+	set, ok := t.channelSeenHostSets[channel]
+	if !ok {
+		return ctxerr.Errorf(ctx, "unsupported host seen channel: %s", channel)
+	}
+	set.addHostID(hostID)
+	return nil
+}
+
 // FlushHostsLastSeen updates the last seen timestamp for the hosts that have
-// been recorded since the last time FlushHostsLastSeen was called. It is a
-// no-op if asychronous host processing is enabled, because then it is the
-// task collector that will process the writes to mysql.
+// been recorded since the last time FlushHostsLastSeen was called. For the
+// osquery seen time, it is a no-op if asychronous host processing is
+// enabled, because then it is the task collector that will process the
+// writes to mysql.
 func (t *Task) FlushHostsLastSeen(ctx context.Context, now time.Time) error {
+	for _, c := range fleet.HostSeenChannels {
+		hostIDs := t.channelSeenHostSets[c].getAndClearHostIDs()
+		if len(hostIDs) == 0 {
+			continue
+		}
+		if err := t.datastore.MarkHostsSeenByChannel(ctx, c, hostIDs, now); err != nil {
+			return ctxerr.Wrapf(ctx, err, "mark hosts seen by %s", c)
+		}
+	}
+
 	cfg := t.taskConfigs[config.AsyncTaskHostLastSeen]
 	if !cfg.Enabled {
 		hostIDs := t.seenHostSet.getAndClearHostIDs()
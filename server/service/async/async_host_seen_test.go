@@ -263,3 +263,41 @@ func testRecordHostLastSeenAsync(t *testing.T, ds *mock.Store, pool fleet.RedisP
 	require.NoError(t, err)
 	require.Equal(t, 0, n)
 }
+
+func TestRecordHostSeenByChannel(t *testing.T) {
+	ctx := context.Background()
+
+	for _, asyncEnabled := range []string{"false", "true"} {
+		t.Run("async "+asyncEnabled, func(t *testing.T) {
+			ds := new(mock.Store)
+			calledWithHostIDs := make(map[fleet.HostSeenChannel][]uint)
+			ds.MarkHostsSeenByChannelFunc = func(ctx context.Context, channel fleet.HostSeenChannel, hostIDs []uint, ts time.Time) error {
+				calledWithHostIDs[channel] = append(calledWithHostIDs[channel], hostIDs...)
+				return nil
+			}
+			ds.MarkHostsSeenFunc = func(ctx context.Context, hostIDs []uint, ts time.Time) error {
+				return nil
+			}
+
+			// the channels' seen times are always recorded in memory, so no redis
+			// pool is needed.
+			task := NewTask(ds, nil, clock.C, config.OsqueryConfig{EnableAsyncHostProcessing: asyncEnabled})
+			require.NoError(t, task.RecordHostSeenByChannel(ctx, 1, fleet.HostSeenChannelOrbit))
+			require.NoError(t, task.RecordHostSeenByChannel(ctx, 2, fleet.HostSeenChannelOrbit))
+			require.NoError(t, task.RecordHostSeenByChannel(ctx, 1, fleet.HostSeenChannelOrbit))
+			require.NoError(t, task.RecordHostSeenByChannel(ctx, 3, fleet.HostSeenChannelFleetDesktop))
+			require.Error(t, task.RecordHostSeenByChannel(ctx, 4, fleet.HostSeenChannel("nope")))
+			require.False(t, ds.MarkHostsSeenByChannelFuncInvoked)
+
+			require.NoError(t, task.FlushHostsLastSeen(ctx, time.Now()))
+			require.Len(t, calledWithHostIDs, 2)
+			require.ElementsMatch(t, []uint{1, 2}, calledWithHostIDs[fleet.HostSeenChannelOrbit])
+			require.Equal(t, []uint{3}, calledWithHostIDs[fleet.HostSeenChannelFleetDesktop])
+
+			// the sets are cleared after a flush
+			ds.MarkHostsSeenByChannelFuncInvoked = false
+			require.NoError(t, task.FlushHostsLastSeen(ctx, time.Now()))
+			require.False(t, ds.MarkHostsSeenByChannelFuncInvoked)
+		})
+	}
+}
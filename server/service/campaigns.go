@@ -19,9 +19,10 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 
 type createDistributedQueryCampaignRequest struct {
-	QuerySQL string            `json:"query"`
-	QueryID  *uint             `json:"query_id"`
-	Selected fleet.HostTargets `json:"selected"`
+	QuerySQL       string                     `json:"query"`
+	QueryID        *uint                      `json:"query_id"`
+	Selected       fleet.HostTargets          `json:"selected"`
+	PostProcessing *fleet.QueryPostProcessing `json:"post_processing"`
 }
 
 type createDistributedQueryCampaignResponse struct {
@@ -33,14 +34,14 @@ func (r createDistributedQueryCampaignResponse) error() error { return r.Err }
 
 func createDistributedQueryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*createDistributedQueryCampaignRequest)
-	campaign, err := svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected)
+	campaign, err := svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected, req.PostProcessing)
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
 	return createDistributedQueryCampaignResponse{Campaign: campaign}, nil
 }
 
-func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets) (*fleet.DistributedQueryCampaign, error) {
+func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, postProcessing *fleet.QueryPostProcessing) (*fleet.DistributedQueryCampaign, error) {
 	if err := svc.StatusLiveQuery(ctx); err != nil {
 		return nil, err
 	}
@@ -53,6 +54,11 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 	if queryID == nil && strings.TrimSpace(queryString) == "" {
 		return nil, fleet.NewInvalidArgumentError("query", "one of query or query_id must be specified")
 	}
+	if postProcessing != nil {
+		if err := postProcessing.Validate(); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "validate post processing")
+		}
+	}
 
 	var query *fleet.Query
 	var err error
@@ -93,9 +99,10 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: query.ObserverCanRun}
 
 	campaign, err := svc.ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
-		QueryID: query.ID,
-		Status:  fleet.QueryWaiting,
-		UserID:  vc.UserID(),
+		QueryID:        query.ID,
+		Status:         fleet.QueryWaiting,
+		UserID:         vc.UserID(),
+		PostProcessing: postProcessing,
 	})
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "new campaign")
@@ -145,6 +152,18 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 		}
 	}
 
+	// Add host group targets
+	for _, gid := range targets.HostGroupIDs {
+		_, err = svc.ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+			Type:                       fleet.TargetHostGroup,
+			DistributedQueryCampaignID: campaign.ID,
+			TargetID:                   gid,
+		})
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "adding host group target")
+		}
+	}
+
 	hostIDs, err := svc.ds.HostIDsInTargets(ctx, filter, targets)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "get target IDs")
@@ -175,9 +194,10 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 ////////////////////////////////////////////////////////////////////////////////
 
 type createDistributedQueryCampaignByNamesRequest struct {
-	QuerySQL string                                 `json:"query"`
-	QueryID  *uint                                  `json:"query_id"`
-	Selected distributedQueryCampaignTargetsByNames `json:"selected"`
+	QuerySQL       string                                 `json:"query"`
+	QueryID        *uint                                  `json:"query_id"`
+	Selected       distributedQueryCampaignTargetsByNames `json:"selected"`
+	PostProcessing *fleet.QueryPostProcessing             `json:"post_processing"`
 }
 
 type distributedQueryCampaignTargetsByNames struct {
@@ -187,14 +207,14 @@ type distributedQueryCampaignTargetsByNames struct {
 
 func createDistributedQueryCampaignByNamesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*createDistributedQueryCampaignByNamesRequest)
-	campaign, err := svc.NewDistributedQueryCampaignByNames(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels)
+	campaign, err := svc.NewDistributedQueryCampaignByNames(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels, req.PostProcessing)
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
 	return createDistributedQueryCampaignResponse{Campaign: campaign}, nil
 }
 
-func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string) (*fleet.DistributedQueryCampaign, error) {
+func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, postProcessing *fleet.QueryPostProcessing) (*fleet.DistributedQueryCampaign, error) {
 	vc, ok := viewer.FromContext(ctx)
 	if !ok {
 		return nil, fleet.ErrNoContext
@@ -217,5 +237,5 @@ func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, quer
 	}
 
 	targets := fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs}
-	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets)
+	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets, postProcessing)
 }
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+var (
+	// changeStreamPollInterval is the interval at which the changes are
+	// loaded from the database while the stream is open.
+	changeStreamPollInterval = 5 * time.Second
+	// changeStreamKeepAliveInterval is the interval at which a comment is sent
+	// when no change happens, so that proxies do not close an idle stream.
+	changeStreamKeepAliveInterval = 30 * time.Second
+	// changeStreamMaxDuration is the duration after which the stream is
+	// closed by the server, the client is expected to reconnect and resume
+	// the stream from the last event it received.
+	changeStreamMaxDuration = 1 * time.Hour
+)
+
+// changeStreamBatchSize is the maximum number of changes of each resource
+// loaded from the database at once.
+const changeStreamBatchSize = 100
+
+////////////////////////////////////////////////////////////////////////////////
+// Stream changes
+////////////////////////////////////////////////////////////////////////////////
+
+type streamChangesRequest struct {
+	Resources []fleet.ChangeStreamResource
+	Cursor    string
+}
+
+func (streamChangesRequest) DecodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req streamChangesRequest
+	if v := r.URL.Query().Get("resources"); v != "" {
+		for _, res := range strings.Split(v, ",") {
+			req.Resources = append(req.Resources, fleet.ChangeStreamResource(strings.TrimSpace(res)))
+		}
+	}
+
+	// the Last-Event-ID header is sent by the EventSource clients when they
+	// reconnect, it takes precedence over the cursor of the URL.
+	req.Cursor = r.URL.Query().Get("cursor")
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		req.Cursor = v
+	}
+	return req, nil
+}
+
+type streamChangesResponse struct {
+	Err error `json:"error,omitempty"`
+
+	// fields below are used in hijackRender for the response.
+	svc    fleet.Service
+	opts   fleet.ChangeStreamOptions
+	events []fleet.ChangeEvent
+	cursor fleet.ChangeStreamCursor
+}
+
+func (r streamChangesResponse) error() error { return r.Err }
+
+func (r streamChangesResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable the response buffering of nginx.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// the stream is open longer than the write timeout of the server.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(changeStreamMaxDuration + time.Minute)); err != nil {
+		logging.WithExtras(ctx, "set_write_deadline_err", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, changeStreamMaxDuration)
+	defer cancel()
+
+	if err := streamChanges(ctx, w, rc.Flush, r.svc, r.opts, r.events, r.cursor); err != nil {
+		// the status code is already sent, the error can only be logged.
+		logging.WithErr(ctx, err)
+	}
+}
+
+// streamChanges writes the events and then the changes loaded from the
+// service until ctx is done, as server-sent events.
+func streamChanges(
+	ctx context.Context, w http.ResponseWriter, flush func() error, svc fleet.Service,
+	opts fleet.ChangeStreamOptions, events []fleet.ChangeEvent, cursor fleet.ChangeStreamCursor,
+) error {
+	// the retry field sets the reconnection delay of EventSource clients.
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", changeStreamPollInterval.Milliseconds()); err != nil {
+		return ctxerr.Wrap(ctx, err, "write change stream retry")
+	}
+
+	lastWrite := time.Now()
+	for {
+		for _, event := range events {
+			if err := writeChangeEvent(w, event); err != nil {
+				return ctxerr.Wrap(ctx, err, "write change event")
+			}
+		}
+		if len(events) > 0 || time.Since(lastWrite) >= changeStreamKeepAliveInterval {
+			if len(events) == 0 {
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return ctxerr.Wrap(ctx, err, "write change stream keep-alive")
+				}
+			}
+			if err := flush(); err != nil {
+				return ctxerr.Wrap(ctx, err, "flush change stream")
+			}
+			lastWrite = time.Now()
+		}
+
+		// load the next batch immediately if there are more changes.
+		if len(events) < changeStreamBatchSize {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(changeStreamPollInterval):
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+
+		opts.Cursor = &cursor
+		var err error
+		events, cursor, err = svc.ListChanges(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return ctxerr.Wrap(ctx, err, "list changes")
+		}
+	}
+}
+
+func writeChangeEvent(w http.ResponseWriter, event fleet.ChangeEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Cursor, event.Type, data)
+	return err
+}
+
+func streamChangesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(streamChangesRequest)
+
+	opts := fleet.ChangeStreamOptions{Resources: req.Resources}
+	if req.Cursor != "" {
+		cursor, err := fleet.ParseChangeStreamCursor(req.Cursor)
+		if err != nil {
+			return streamChangesResponse{Err: fleet.NewInvalidArgumentError("cursor", err.Error())}, nil
+		}
+		opts.Cursor = &cursor
+	}
+
+	// the first changes are loaded before the stream is open, so that an
+	// error is returned with the appropriate status code.
+	events, cursor, err := svc.ListChanges(ctx, opts)
+	if err != nil {
+		return streamChangesResponse{Err: err}, nil
+	}
+	return streamChangesResponse{svc: svc, opts: opts, events: events, cursor: cursor}, nil
+}
+
+func (svc *Service) ListChanges(ctx context.Context, opts fleet.ChangeStreamOptions) ([]fleet.ChangeEvent, fleet.ChangeStreamCursor, error) {
+	for _, res := range opts.Resources {
+		if !res.IsValid() {
+			svc.authz.SkipAuthorization(ctx)
+			return nil, fleet.ChangeStreamCursor{}, fleet.NewInvalidArgumentError("resources", "unsupported resource: "+strconv.Quote(string(res)))
+		}
+	}
+
+	includeHosts := opts.Includes(fleet.ChangeStreamResourceHosts)
+	includeActivities := opts.Includes(fleet.ChangeStreamResourceActivities)
+	if includeHosts {
+		if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+			return nil, fleet.ChangeStreamCursor{}, err
+		}
+	}
+	if includeActivities {
+		if err := svc.authz.Authorize(ctx, &fleet.Activity{}, fleet.ActionRead); err != nil {
+			return nil, fleet.ChangeStreamCursor{}, err
+		}
+	}
+
+	if opts.Cursor == nil {
+		// start the stream from the current time.
+		cursor := fleet.ChangeStreamCursor{HostUpdatedAt: svc.clock.Now().UTC().Truncate(time.Second)}
+		activities, _, err := svc.ds.ListActivities(ctx, fleet.ListActivitiesOptions{
+			ListOptions: fleet.ListOptions{OrderKey: "a.id", OrderDirection: fleet.OrderDescending, PerPage: 1},
+		})
+		if err != nil {
+			return nil, fleet.ChangeStreamCursor{}, ctxerr.Wrap(ctx, err, "get last activity")
+		}
+		if len(activities) > 0 {
+			cursor.ActivityID = activities[0].ID
+		}
+		return nil, cursor, nil
+	}
+
+	cursor := *opts.Cursor
+	var events []fleet.ChangeEvent
+
+	if includeHosts {
+		vc, ok := viewer.FromContext(ctx)
+		if !ok {
+			return nil, fleet.ChangeStreamCursor{}, fleet.ErrNoContext
+		}
+		filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+		hosts, err := svc.ds.ListHostChanges(ctx, filter, cursor.HostUpdatedAt, cursor.HostID, changeStreamBatchSize)
+		if err != nil {
+			return nil, fleet.ChangeStreamCursor{}, ctxerr.Wrap(ctx, err, "list host changes")
+		}
+		for _, h := range hosts {
+			cursor.HostUpdatedAt = h.UpdatedAt
+			cursor.HostID = h.ID
+			events = append(events, fleet.ChangeEvent{Type: fleet.ChangeEventHostUpdated, Cursor: cursor, Data: h})
+		}
+	}
+
+	if includeActivities {
+		activities, _, err := svc.ds.ListActivities(ctx, fleet.ListActivitiesOptions{
+			ListOptions: fleet.ListOptions{
+				OrderKey: "a.id",
+				After:    strconv.FormatUint(uint64(cursor.ActivityID), 10),
+				PerPage:  changeStreamBatchSize,
+			},
+		})
+		if err != nil {
+			return nil, fleet.ChangeStreamCursor{}, ctxerr.Wrap(ctx, err, "list new activities")
+		}
+		for _, a := range activities {
+			cursor.ActivityID = a.ID
+			events = append(events, fleet.ChangeEvent{Type: fleet.ChangeEventActivityCreated, Cursor: cursor, Data: a})
+		}
+	}
+
+	return events, cursor, nil
+}
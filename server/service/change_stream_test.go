@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListChangesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.ListHostChangesFunc = func(ctx context.Context, filter fleet.TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*fleet.HostChange, error) {
+		return nil, nil
+	}
+	ds.ListActivitiesFunc = func(ctx context.Context, opt fleet.ListActivitiesOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+		return nil, nil, nil
+	}
+
+	testCases := []struct {
+		name                   string
+		user                   *fleet.User
+		shouldFailHosts        bool
+		shouldFailAllResources bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			false,
+			false,
+		},
+		{
+			"team admin",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			false,
+			true,
+		},
+		{
+			"team observer",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			false,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			cursor := &fleet.ChangeStreamCursor{}
+
+			_, _, err := svc.ListChanges(ctx, fleet.ChangeStreamOptions{
+				Resources: []fleet.ChangeStreamResource{fleet.ChangeStreamResourceHosts},
+				Cursor:    cursor,
+			})
+			checkAuthErr(t, tt.shouldFailHosts, err)
+
+			_, _, err = svc.ListChanges(ctx, fleet.ChangeStreamOptions{Cursor: cursor})
+			checkAuthErr(t, tt.shouldFailAllResources, err)
+		})
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	updatedAt := time.Date(2024, 6, 4, 9, 0, 0, 0, time.UTC)
+	ds.ListHostChangesFunc = func(ctx context.Context, filter fleet.TeamFilter, afterUpdatedAt time.Time, afterID uint, limit int) ([]*fleet.HostChange, error) {
+		require.Equal(t, changeStreamBatchSize, limit)
+		if afterID > 0 {
+			return nil, nil
+		}
+		return []*fleet.HostChange{
+			{ID: 2, Hostname: "h2", UpdatedAt: updatedAt},
+			{ID: 1, Hostname: "h1", UpdatedAt: updatedAt.Add(time.Second)},
+		}, nil
+	}
+	ds.ListActivitiesFunc = func(ctx context.Context, opt fleet.ListActivitiesOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+		require.Equal(t, "a.id", opt.ListOptions.OrderKey)
+		if opt.ListOptions.OrderDirection == fleet.OrderDescending {
+			// last activity
+			return []*fleet.Activity{{ID: 10}}, nil, nil
+		}
+		require.Equal(t, "10", opt.ListOptions.After)
+		return []*fleet.Activity{{ID: 11}, {ID: 12}}, nil, nil
+	}
+
+	t.Run("invalid resource", func(t *testing.T) {
+		_, _, err := svc.ListChanges(ctx, fleet.ChangeStreamOptions{Resources: []fleet.ChangeStreamResource{"users"}})
+		var iae *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &iae)
+	})
+
+	t.Run("no cursor", func(t *testing.T) {
+		before := time.Now().UTC().Truncate(time.Second)
+		events, cursor, err := svc.ListChanges(ctx, fleet.ChangeStreamOptions{})
+		require.NoError(t, err)
+		require.Empty(t, events)
+		require.EqualValues(t, 10, cursor.ActivityID)
+		require.Zero(t, cursor.HostID)
+		require.False(t, cursor.HostUpdatedAt.Before(before))
+	})
+
+	t.Run("with cursor", func(t *testing.T) {
+		events, cursor, err := svc.ListChanges(ctx, fleet.ChangeStreamOptions{
+			Cursor: &fleet.ChangeStreamCursor{ActivityID: 10, HostUpdatedAt: updatedAt.Add(-time.Hour)},
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 4)
+		require.Equal(t, fleet.ChangeEventHostUpdated, events[0].Type)
+		require.Equal(t, fleet.ChangeStreamCursor{ActivityID: 10, HostUpdatedAt: updatedAt, HostID: 2}, events[0].Cursor)
+		require.Equal(t, fleet.ChangeEventHostUpdated, events[1].Type)
+		require.Equal(t, fleet.ChangeEventActivityCreated, events[2].Type)
+		require.Equal(t, fleet.ChangeStreamCursor{ActivityID: 11, HostUpdatedAt: updatedAt.Add(time.Second), HostID: 1}, events[2].Cursor)
+		require.Equal(t, fleet.ChangeEventActivityCreated, events[3].Type)
+		require.Equal(t, fleet.ChangeStreamCursor{ActivityID: 12, HostUpdatedAt: updatedAt.Add(time.Second), HostID: 1}, cursor)
+		require.Equal(t, cursor, events[3].Cursor)
+
+		// only the hosts
+		events, _, err = svc.ListChanges(ctx, fleet.ChangeStreamOptions{
+			Resources: []fleet.ChangeStreamResource{fleet.ChangeStreamResourceHosts},
+			Cursor:    &fleet.ChangeStreamCursor{ActivityID: 10},
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	})
+}
+
+func TestStreamChanges(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	origPoll, origKeepAlive := changeStreamPollInterval, changeStreamKeepAliveInterval
+	changeStreamPollInterval, changeStreamKeepAliveInterval = 10*time.Millisecond, 0
+	t.Cleanup(func() {
+		changeStreamPollInterval, changeStreamKeepAliveInterval = origPoll, origKeepAlive
+	})
+
+	// a new activity is created on each poll
+	var lastActivityID uint
+	ds.ListActivitiesFunc = func(ctx context.Context, opt fleet.ListActivitiesOptions) ([]*fleet.Activity, *fleet.PaginationMetadata, error) {
+		if lastActivityID == 2 {
+			return nil, nil, nil
+		}
+		lastActivityID++
+		return []*fleet.Activity{{ID: lastActivityID, Type: "test"}}, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+
+	opts := fleet.ChangeStreamOptions{Resources: []fleet.ChangeStreamResource{fleet.ChangeStreamResourceActivities}}
+	events, cursor, err := svc.ListChanges(ctx, fleet.ChangeStreamOptions{
+		Resources: opts.Resources,
+		Cursor:    &fleet.ChangeStreamCursor{},
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, streamChanges(ctx, rec, func() error { return nil }, svc, opts, events, cursor))
+
+	body := rec.Body.String()
+	require.True(t, strings.HasPrefix(body, "retry: 10\n\n"), body)
+	require.Contains(t, body, "id: "+fleet.ChangeStreamCursor{ActivityID: 1}.String()+"\nevent: activity_created\ndata: {")
+	require.Contains(t, body, "id: "+fleet.ChangeStreamCursor{ActivityID: 2}.String()+"\nevent: activity_created\ndata: {")
+	require.Contains(t, body, ": keep-alive\n\n")
+}
@@ -312,9 +312,12 @@ func getProfilesContents(baseDir string, profiles []fleet.MDMProfileSpec) ([]fle
 		}
 		extByName[name] = ext
 		result = append(result, fleet.MDMProfileBatchPayload{
-			Name:     name,
-			Contents: fileContents,
-			Labels:   profile.Labels,
+			Name:             name,
+			Contents:         fileContents,
+			Labels:           profile.Labels,
+			LabelsIncludeAny: profile.LabelsIncludeAny,
+			LabelsExcludeAny: profile.LabelsExcludeAny,
+			Rollout:          profile.Rollout,
 		})
 
 	}
@@ -600,6 +603,17 @@ func (c *Client) ApplyGroup(
 			logfn("[+] applied user roles\n")
 		}
 	}
+
+	if specs.HostCustomFields != nil {
+		if opts.DryRun {
+			logfn("[!] ignoring host custom fields, dry run mode only supported for 'config' and 'team' specs\n")
+		} else {
+			if err := c.ApplyHostCustomFieldsSpec(*specs.HostCustomFields); err != nil {
+				return nil, fmt.Errorf("applying host custom fields: %w", err)
+			}
+			logfn("[+] applied host custom fields for %d hosts\n", len(specs.HostCustomFields.Hosts))
+		}
+	}
 	return teamIDsByName, nil
 }
 
@@ -683,13 +697,29 @@ func extractAppCfgCustomSettings(appCfg interface{}, platformKey string) []fleet
 				profSpec.Path = path
 			}
 
-			// extract the Labels field, labels are cleared if not provided
-			if labels, ok := m["labels"].([]interface{}); ok {
-				for _, label := range labels {
-					if strLabel, ok := label.(string); ok {
-						profSpec.Labels = append(profSpec.Labels, strLabel)
+			// extract the label fields, labels are cleared if not provided
+			extractLabels := func(key string) []string {
+				var names []string
+				if labels, ok := m[key].([]interface{}); ok {
+					for _, label := range labels {
+						if strLabel, ok := label.(string); ok {
+							names = append(names, strLabel)
+						}
 					}
 				}
+				return names
+			}
+			profSpec.Labels = extractLabels("labels")
+			profSpec.LabelsIncludeAny = extractLabels("labels_include_any")
+			profSpec.LabelsExcludeAny = extractLabels("labels_exclude_any")
+
+			// extract the staged rollout settings, an invalid rollout is left
+			// for the server to reject.
+			if rollout, ok := m["rollout"].(map[string]interface{}); ok {
+				profSpec.Rollout = &fleet.MDMProfileRolloutSettings{}
+				if b, err := json.Marshal(rollout); err == nil {
+					_ = json.Unmarshal(b, profSpec.Rollout)
+				}
 			}
 
 			if profSpec.Path != "" {
@@ -944,6 +974,9 @@ func (c *Client) DoGitOps(
 		if hostExpirySettings, ok := config.TeamSettings["host_expiry_settings"]; ok {
 			team["host_expiry_settings"] = hostExpirySettings
 		}
+		if fileRetrieval, ok := config.TeamSettings["file_retrieval"]; ok {
+			team["file_retrieval"] = fileRetrieval
+		}
 		if features, ok := config.TeamSettings["features"]; ok {
 			team["features"] = features
 		}
@@ -1063,6 +1096,12 @@ func (c *Client) DoGitOps(
 		group.Teams = []json.RawMessage{rawTeam}
 	}
 
+	// Upload the secret variables referenced by scripts and profiles, so that
+	// they exist when those are applied.
+	if err := c.doGitOpsSecretVariables(baseDir, scripts, mdmAppConfig, logFn, dryRun); err != nil {
+		return err
+	}
+
 	// Apply org settings, scripts, enroll secrets, and controls
 	teamIDsByName, err := c.ApplyGroup(ctx, &group, baseDir, logf, fleet.ApplySpecOptions{DryRun: dryRun})
 	if err != nil {
@@ -1093,6 +1132,57 @@ func (c *Client) DoGitOps(
 	return nil
 }
 
+// doGitOpsSecretVariables uploads the secret variables referenced by the
+// provided scripts and by the custom settings of the mdm config. The values
+// are read from the environment variables of the same name, e.g.
+// FLEET_SECRET_API_KEY for $FLEET_SECRET_API_KEY.
+func (c *Client) doGitOpsSecretVariables(
+	baseDir string, scripts []interface{}, mdmConfig map[string]interface{}, logFn func(format string, args ...interface{}), dryRun bool,
+) error {
+	paths := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		if p, ok := script.(string); ok && p != "" {
+			paths = append(paths, p)
+		}
+	}
+	appCfg := map[string]interface{}{"mdm": mdmConfig}
+	profiles := append(extractAppCfgMacOSCustomSettings(appCfg), extractAppCfgWindowsCustomSettings(appCfg)...)
+	for _, prof := range profiles {
+		paths = append(paths, prof.Path)
+	}
+
+	seen := make(map[string]bool)
+	var secrets []fleet.SecretVariablePayload
+	for _, p := range resolveApplyRelativePaths(baseDir, paths) {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading %s for secret variables: %w", p, err)
+		}
+		for _, name := range fleet.ReferencedSecretVariables(string(b)) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			envName := fleet.SecretVariablePrefix + name
+			value := os.Getenv(envName)
+			if value == "" {
+				return fmt.Errorf("environment variable %q is not set, it is referenced by %s", envName, p)
+			}
+			secrets = append(secrets, fleet.SecretVariablePayload{Name: name, Value: value})
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	logFn("[+] syncing %d secret variables\n", len(secrets))
+	if err := c.SaveSecretVariables(secrets, dryRun); err != nil {
+		return fmt.Errorf("error applying secret variables: %w", err)
+	}
+	return nil
+}
+
 func (c *Client) doGitOpsPolicies(config *spec.GitOps, logFn func(format string, args ...interface{}), dryRun bool) error {
 	// Get the ids and names of current policies to figure out which ones to delete
 	policies, err := c.GetPolicies(config.TeamID)
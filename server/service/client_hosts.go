@@ -138,6 +138,31 @@ func (c *Client) TransferHosts(hosts []string, label string, status, searchQuery
 	return c.authenticatedRequest(params, verb, path, &responseBody)
 }
 
+// UpdateHostCustomFields updates the notes and custom fields of the host with
+// the provided identifier (hostname, UUID or serial number).
+func (c *Client) UpdateHostCustomFields(identifier string, payload fleet.HostCustomFieldsPayload) (*fleet.HostCustomFields, error) {
+	host, err := c.HostByIdentifier(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("get host by identifier: %w", err)
+	}
+
+	verb, path := "PATCH", fmt.Sprintf("/api/latest/fleet/hosts/%d/custom_fields", host.ID)
+	params := updateHostCustomFieldsRequest{HostCustomFieldsPayload: payload}
+	var responseBody updateHostCustomFieldsResponse
+	if err := c.authenticatedRequest(params, verb, path, &responseBody); err != nil {
+		return nil, err
+	}
+	return responseBody.HostCustomFields, nil
+}
+
+// ApplyHostCustomFieldsSpec sends the notes and custom fields of the hosts to
+// be applied to the Fleet instance.
+func (c *Client) ApplyHostCustomFieldsSpec(spec fleet.HostCustomFieldsSpec) error {
+	verb, path := "POST", "/api/latest/fleet/spec/host_custom_fields"
+	var responseBody applyHostCustomFieldsSpecResponse
+	return c.authenticatedRequest(applyHostCustomFieldsSpecRequest{Spec: &spec}, verb, path, &responseBody)
+}
+
 // GetHostsReport returns a report of all hosts.
 //
 // The first row holds the name of the columns and each subsequent row are
@@ -373,12 +373,15 @@ func (c *Client) prepareAppleMDMCommand(rawCmd []byte) ([]byte, error) {
 	return b, nil
 }
 
-func (c *Client) MDMLockHost(hostID uint) error {
+// MDMLockHost requests the host to be locked. It returns true if the request
+// must be approved by a second user before the host is locked.
+func (c *Client) MDMLockHost(hostID uint, confirmation string) (bool, error) {
+	request := lockHostRequest{Confirmation: confirmation}
 	var response lockHostResponse
-	if err := c.authenticatedRequest(nil, "POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", hostID), &response); err != nil {
-		return fmt.Errorf("lock host request: %w", err)
+	if err := c.authenticatedRequest(request, "POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", hostID), &response); err != nil {
+		return false, fmt.Errorf("lock host request: %w", err)
 	}
-	return nil
+	return response.PendingApproval, nil
 }
 
 func (c *Client) MDMUnlockHost(hostID uint) (string, error) {
@@ -389,10 +392,84 @@ func (c *Client) MDMUnlockHost(hostID uint) (string, error) {
 	return response.UnlockPIN, nil
 }
 
-func (c *Client) MDMWipeHost(hostID uint) error {
+func (c *Client) MDMGetHostUnlockPIN(hostID uint) (string, error) {
+	var response getHostUnlockPINResponse
+	if err := c.authenticatedRequest(nil, "GET", fmt.Sprintf("/api/latest/fleet/hosts/%d/unlock_pin", hostID), &response); err != nil {
+		return "", fmt.Errorf("get host unlock PIN request: %w", err)
+	}
+	return response.UnlockPIN, nil
+}
+
+// MDMWipeHost requests the host to be wiped. It returns true if the request
+// must be approved by a second user before the host is wiped.
+func (c *Client) MDMWipeHost(hostID uint, confirmation string) (bool, error) {
+	request := wipeHostRequest{Confirmation: confirmation}
 	var response wipeHostResponse
-	if err := c.authenticatedRequest(nil, "POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/wipe", hostID), &response); err != nil {
-		return fmt.Errorf("wipe host request: %w", err)
+	if err := c.authenticatedRequest(request, "POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/wipe", hostID), &response); err != nil {
+		return false, fmt.Errorf("wipe host request: %w", err)
 	}
-	return nil
+	return response.PendingApproval, nil
+}
+
+// ExportMDMAssets returns the bundle of the MDM assets of the server,
+// encrypted with the passphrase.
+func (c *Client) ExportMDMAssets(passphrase string) ([]byte, error) {
+	verb, path := "POST", "/api/latest/fleet/mdm/assets/export"
+	response, err := c.AuthenticatedDo(verb, path, "", exportMDMAssetsRequest{Passphrase: passphrase})
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", verb, path, err)
+	}
+	defer response.Body.Close()
+
+	if err := c.parseResponse(verb, path, response, nil); err != nil {
+		return nil, err
+	}
+	bundle, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading MDM assets bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+// ImportMDMAssets restores the MDM assets of a bundle exported by
+// ExportMDMAssets.
+func (c *Client) ImportMDMAssets(bundle []byte, passphrase string, overwrite bool) (*fleet.MDMAssetsImportResult, error) {
+	verb, path := "POST", "/api/latest/fleet/mdm/assets/import"
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	fw, err := w.CreateFormFile("bundle", "fleet-mdm-assets.bundle")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(bundle); err != nil {
+		return nil, err
+	}
+	if err := w.WriteField("passphrase", passphrase); err != nil {
+		return nil, err
+	}
+	if err := w.WriteField("overwrite", fmt.Sprint(overwrite)); err != nil {
+		return nil, err
+	}
+	w.Close()
+
+	response, err := c.doContextWithBodyAndHeaders(context.Background(), verb, path, "",
+		b.Bytes(),
+		map[string]string{
+			"Content-Type":  w.FormDataContentType(),
+			"Accept":        "application/json",
+			"Authorization": fmt.Sprintf("Bearer %s", c.token),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("do multipart request: %w", err)
+	}
+	defer response.Body.Close()
+
+	var importResponse importMDMAssetsResponse
+	if err := c.parseResponse(verb, path, response, &importResponse); err != nil {
+		return nil, err
+	}
+	return importResponse.MDMAssetsImportResult, nil
 }
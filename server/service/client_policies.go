@@ -2,6 +2,8 @@ package service
 
 import (
 	"fmt"
+	"net/url"
+
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
@@ -58,3 +60,28 @@ func (c *Client) DeletePolicies(teamID *uint, IDs []uint) error {
 	var responseBody deleteGlobalPoliciesResponse
 	return c.authenticatedRequest(req, verb, path, &responseBody)
 }
+
+// GetPolicyComplianceTrends retrieves the daily policy compliance of the team
+// (or of all hosts if teamID is nil) between the two dates, in the YYYY-MM-DD
+// format. The dates and the policy are optional.
+func (c *Client) GetPolicyComplianceTrends(teamID, policyID *uint, startDate, endDate string) (*fleet.PolicyComplianceTrends, error) {
+	verb, path := "GET", "/api/latest/fleet/policies/trends"
+	query := url.Values{}
+	if teamID != nil {
+		query.Set("team_id", fmt.Sprint(*teamID))
+	}
+	if policyID != nil {
+		query.Set("policy_id", fmt.Sprint(*policyID))
+	}
+	if startDate != "" {
+		query.Set("start_date", startDate)
+	}
+	if endDate != "" {
+		query.Set("end_date", endDate)
+	}
+	var responseBody getPolicyComplianceTrendsResponse
+	if err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, query.Encode()); err != nil {
+		return nil, err
+	}
+	return responseBody.PolicyComplianceTrends, nil
+}
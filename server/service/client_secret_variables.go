@@ -0,0 +1,11 @@
+package service
+
+import "github.com/fleetdm/fleet/v4/server/fleet"
+
+// SaveSecretVariables creates the provided secret variables, or updates the
+// value of those that already exist.
+func (c *Client) SaveSecretVariables(secrets []fleet.SecretVariablePayload, dryRun bool) error {
+	verb, path := "PUT", "/api/latest/fleet/spec/secret_variables"
+	opts := fleet.ApplySpecOptions{DryRun: dryRun}
+	return c.authenticatedRequestWithQuery(map[string]interface{}{"secrets": secrets}, verb, path, nil, opts.RawQuery())
+}
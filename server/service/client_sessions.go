@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -41,6 +42,9 @@ func (c *Client) Login(email, password string) (string, error) {
 	if responseBody.Err != nil {
 		return "", fmt.Errorf("login: %s", responseBody.Err)
 	}
+	if responseBody.MFARequired {
+		return "", errors.New("login: multi-factor authentication is enabled for this user, which fleetctl does not support; use an API-only user instead")
+	}
 
 	return responseBody.Token, nil
 }
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/fleetdm/fleet/v4/pkg/optjson"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List config versions
+////////////////////////////////////////////////////////////////////////////////
+
+type listConfigVersionsRequest struct {
+	TeamID      *uint             `query:"team_id,optional"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listConfigVersionsResponse struct {
+	Versions []*fleet.ConfigVersion    `json:"versions"`
+	Meta     *fleet.PaginationMetadata `json:"meta"`
+	Err      error                     `json:"error,omitempty"`
+}
+
+func (r listConfigVersionsResponse) error() error { return r.Err }
+
+func listConfigVersionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listConfigVersionsRequest)
+	versions, meta, err := svc.ListConfigVersions(ctx, req.TeamID, req.ListOptions)
+	if err != nil {
+		return listConfigVersionsResponse{Err: err}, nil
+	}
+	return listConfigVersionsResponse{Versions: versions, Meta: meta}, nil
+}
+
+func (svc *Service) ListConfigVersions(ctx context.Context, teamID *uint, opts fleet.ListOptions) ([]*fleet.ConfigVersion, *fleet.PaginationMetadata, error) {
+	if err := svc.authorizeConfigVersion(ctx, teamID, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+
+	opts.IncludeMetadata = true
+	return svc.ds.ListConfigVersions(ctx, teamID, opts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get config version
+////////////////////////////////////////////////////////////////////////////////
+
+type getConfigVersionRequest struct {
+	ID uint `url:"id"`
+}
+
+type getConfigVersionResponse struct {
+	Version *fleet.ConfigVersion `json:"version,omitempty"`
+	Err     error                `json:"error,omitempty"`
+}
+
+func (r getConfigVersionResponse) error() error { return r.Err }
+
+func getConfigVersionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getConfigVersionRequest)
+	version, err := svc.GetConfigVersion(ctx, req.ID)
+	if err != nil {
+		return getConfigVersionResponse{Err: err}, nil
+	}
+	return getConfigVersionResponse{Version: version}, nil
+}
+
+func (svc *Service) GetConfigVersion(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+	version, err := svc.configVersion(ctx, id, fleet.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	if err := obfuscateConfigVersion(version); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "obfuscate config version")
+	}
+	return version, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Diff config versions
+////////////////////////////////////////////////////////////////////////////////
+
+type diffConfigVersionsRequest struct {
+	FromID uint `query:"from"`
+	ToID   uint `query:"to"`
+}
+
+type diffConfigVersionsResponse struct {
+	*fleet.ConfigVersionDiff
+	Err error `json:"error,omitempty"`
+}
+
+func (r diffConfigVersionsResponse) error() error { return r.Err }
+
+func diffConfigVersionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*diffConfigVersionsRequest)
+	diff, err := svc.DiffConfigVersions(ctx, req.FromID, req.ToID)
+	if err != nil {
+		return diffConfigVersionsResponse{Err: err}, nil
+	}
+	return diffConfigVersionsResponse{ConfigVersionDiff: diff}, nil
+}
+
+func (svc *Service) DiffConfigVersions(ctx context.Context, fromID, toID uint) (*fleet.ConfigVersionDiff, error) {
+	from, err := svc.configVersion(ctx, fromID, fleet.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	to, err := svc.configVersion(ctx, toID, fleet.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	if !sameTeamID(from.TeamID, to.TeamID) {
+		return nil, fleet.NewInvalidArgumentError("to", "configuration versions must be versions of the same configuration")
+	}
+
+	// credentials are obfuscated before computing the returned diff so that
+	// they are not leaked, the changes that only affect credentials are
+	// reported with masked values.
+	rawFrom, rawTo := *from, *to
+	if err := normalizeConfigVersion(&rawFrom, false); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "normalize config version")
+	}
+	if err := normalizeConfigVersion(&rawTo, false); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "normalize config version")
+	}
+	rawDiff, err := fleet.DiffConfigVersions(&rawFrom, &rawTo)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "diff config versions")
+	}
+
+	if err := normalizeConfigVersion(from, true); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "obfuscate config version")
+	}
+	if err := normalizeConfigVersion(to, true); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "obfuscate config version")
+	}
+	diff, err := fleet.DiffConfigVersions(from, to)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "diff config versions")
+	}
+
+	reported := make(map[string]bool, len(diff.Changes))
+	for _, c := range diff.Changes {
+		reported[c.Path] = true
+	}
+	var masked bool
+	for _, c := range rawDiff.Changes {
+		if !reported[c.Path] {
+			diff.Changes = append(diff.Changes, fleet.ConfigVersionChange{Path: c.Path, Old: fleet.MaskedPassword, New: fleet.MaskedPassword})
+			masked = true
+		}
+	}
+	if masked {
+		sort.Slice(diff.Changes, func(i, j int) bool {
+			return diff.Changes[i].Path < diff.Changes[j].Path
+		})
+	}
+	return diff, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Rollback config version
+////////////////////////////////////////////////////////////////////////////////
+
+type rollbackConfigVersionRequest struct {
+	ID uint `url:"id"`
+}
+
+type rollbackConfigVersionResponse struct {
+	Version *fleet.ConfigVersion `json:"version,omitempty"`
+	Err     error                `json:"error,omitempty"`
+}
+
+func (r rollbackConfigVersionResponse) error() error { return r.Err }
+
+func rollbackConfigVersionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*rollbackConfigVersionRequest)
+	version, err := svc.RollbackConfigVersion(ctx, req.ID)
+	if err != nil {
+		return rollbackConfigVersionResponse{Err: err}, nil
+	}
+	return rollbackConfigVersionResponse{Version: version}, nil
+}
+
+func (svc *Service) RollbackConfigVersion(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+	version, err := svc.configVersion(ctx, id, fleet.ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the latest version reflects the current configuration, to
+	// know if the profiles need to be restored.
+	current, err := svc.ds.NewConfigVersion(ctx, version.TeamID, authz.UserFromContext(ctx))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record current config version")
+	}
+
+	var teamName *string
+	if version.TeamID == nil {
+		if _, err := svc.ModifyAppConfig(ctx, version.Config, fleet.ApplySpecOptions{}); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "restore app config")
+		}
+	} else {
+		team, err := svc.rollbackTeamConfig(ctx, *version.TeamID, version.Config)
+		if err != nil {
+			return nil, err
+		}
+		teamName = &team.Name
+	}
+
+	if !equalConfigVersionProfiles(current.Profiles, version.Profiles) {
+		profiles := make([]fleet.MDMProfileBatchPayload, 0, len(version.Profiles))
+		for _, p := range version.Profiles {
+			profiles = append(profiles, fleet.MDMProfileBatchPayload{
+				Name:             p.Name,
+				Contents:         p.Contents,
+				Labels:           p.Labels,
+				LabelsIncludeAny: p.LabelsIncludeAny,
+				LabelsExcludeAny: p.LabelsExcludeAny,
+				Rollout:          p.Rollout,
+			})
+		}
+		if err := svc.BatchSetMDMProfiles(ctx, version.TeamID, nil, profiles, false, false, false); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "restore configuration profiles")
+		}
+	}
+
+	restored, err := svc.ds.NewConfigVersion(ctx, version.TeamID, authz.UserFromContext(ctx))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record restored config version")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeRolledBackConfig{
+		VersionID: version.ID,
+		TeamID:    version.TeamID,
+		TeamName:  teamName,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for config rollback")
+	}
+
+	if err := obfuscateConfigVersion(restored); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "obfuscate config version")
+	}
+	return restored, nil
+}
+
+// rollbackTeamConfig restores the team configuration via the same service
+// methods used to modify it, so that the side effects of the changes (e.g.
+// enabling disk encryption) are applied.
+func (svc *Service) rollbackTeamConfig(ctx context.Context, teamID uint, rawConfig json.RawMessage) (*fleet.Team, error) {
+	var cfg fleet.TeamConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal team config")
+	}
+
+	team, err := svc.ModifyTeam(ctx, teamID, fleet.TeamPayload{
+		WebhookSettings: &cfg.WebhookSettings,
+		Integrations:    &cfg.Integrations,
+		MDM: &fleet.TeamPayloadMDM{
+			EnableDiskEncryption: optjson.SetBool(cfg.MDM.EnableDiskEncryption),
+			MacOSUpdates:         &cfg.MDM.MacOSUpdates,
+			WindowsUpdates:       &cfg.MDM.WindowsUpdates,
+			MacOSSetup:           &cfg.MDM.MacOSSetup,
+		},
+		HostExpirySettings: &cfg.HostExpirySettings,
+		FileRetrieval:      &cfg.FileRetrieval,
+		LockWipeSettings:   &cfg.LockWipeSettings,
+		RemoteQueries:      &cfg.RemoteQueries,
+		VulnerabilitySLA:   &cfg.VulnerabilitySLA,
+		Localization:       &cfg.Localization,
+		Branding:           &cfg.Branding,
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "restore team config")
+	}
+
+	if cfg.AgentOptions != nil {
+		if team, err = svc.ModifyTeamAgentOptions(ctx, teamID, *cfg.AgentOptions, fleet.ApplySpecOptions{}); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "restore team agent options")
+		}
+	}
+
+	// the features can only be set via team specs, there are no side effects to
+	// changing them.
+	currentFeatures, err := json.Marshal(team.Config.Features)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal team features")
+	}
+	features, err := json.Marshal(cfg.Features)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal team features")
+	}
+	if !bytes.Equal(currentFeatures, features) {
+		team.Config.Features = cfg.Features
+		if team, err = svc.ds.SaveTeam(ctx, team); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "restore team features")
+		}
+	}
+	return team, nil
+}
+
+// configVersion loads the configuration version identified by id and
+// authorizes the action on the configuration it is a version of.
+func (svc *Service) configVersion(ctx context.Context, id uint, action string) (*fleet.ConfigVersion, error) {
+	// first we perform a basic authz check
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	version, err := svc.ds.ConfigVersion(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get config version")
+	}
+
+	// now we can do a specific authz check based on the team of the version
+	if err := svc.authorizeConfigVersion(ctx, version.TeamID, action); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+func (svc *Service) authorizeConfigVersion(ctx context.Context, teamID *uint, action string) error {
+	if teamID == nil {
+		return svc.authz.Authorize(ctx, &fleet.AppConfig{}, action)
+	}
+	return svc.authz.Authorize(ctx, &fleet.Team{ID: *teamID}, action)
+}
+
+// obfuscateConfigVersion obfuscates the credentials in the configuration of
+// the version.
+func obfuscateConfigVersion(version *fleet.ConfigVersion) error {
+	return normalizeConfigVersion(version, true)
+}
+
+// normalizeConfigVersion re-encodes the configuration of the version from the
+// current AppConfig or TeamConfig struct so that versions recorded before
+// settings were added can be compared with recent ones. If obfuscate is true,
+// the credentials are obfuscated.
+func normalizeConfigVersion(version *fleet.ConfigVersion, obfuscate bool) error {
+	var cfg any
+	if version.TeamID == nil {
+		var appCfg fleet.AppConfig
+		if err := json.Unmarshal(version.Config, &appCfg); err != nil {
+			return err
+		}
+		if obfuscate {
+			appCfg.Obfuscate()
+		}
+		cfg = &appCfg
+	} else {
+		var teamCfg fleet.TeamConfig
+		if err := json.Unmarshal(version.Config, &teamCfg); err != nil {
+			return err
+		}
+		cfg = &teamCfg
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	version.Config = b
+	return nil
+}
+
+func equalConfigVersionProfiles(a, b []fleet.ConfigVersionProfile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	diff, err := fleet.DiffConfigVersions(
+		&fleet.ConfigVersion{Config: json.RawMessage(`{}`), Profiles: a},
+		&fleet.ConfigVersion{Config: json.RawMessage(`{}`), Profiles: b},
+	)
+	return err == nil && len(diff.ProfilesAdded)+len(diff.ProfilesRemoved)+len(diff.ProfilesModified) == 0
+}
+
+func sameTeamID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigVersionsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	versions := map[uint]*fleet.ConfigVersion{
+		1: {ID: 1, Config: json.RawMessage(`{}`)},
+		2: {ID: 2, Config: json.RawMessage(`{}`)},
+		3: {ID: 3, TeamID: ptr.Uint(1), Config: json.RawMessage(`{}`)},
+		4: {ID: 4, TeamID: ptr.Uint(1), Config: json.RawMessage(`{}`)},
+	}
+	ds.ConfigVersionFunc = func(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+		v := *versions[id]
+		return &v, nil
+	}
+	ds.ListConfigVersionsFunc = func(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.ConfigVersion, *fleet.PaginationMetadata, error) {
+		return nil, &fleet.PaginationMetadata{}, nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalWrite bool
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, true, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, false, true},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, false, false},
+		{"team observer", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, false, true},
+		{"other team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, true, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			// the global configuration can be read by all users, as with the
+			// get app config endpoint.
+			_, _, err := svc.ListConfigVersions(ctx, nil, fleet.ListOptions{})
+			checkAuthErr(t, false, err)
+			_, err = svc.GetConfigVersion(ctx, 1)
+			checkAuthErr(t, false, err)
+			_, err = svc.DiffConfigVersions(ctx, 1, 2)
+			checkAuthErr(t, false, err)
+
+			_, _, err = svc.ListConfigVersions(ctx, ptr.Uint(1), fleet.ListOptions{})
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+			_, err = svc.GetConfigVersion(ctx, 3)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+			_, err = svc.DiffConfigVersions(ctx, 3, 4)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			if tt.shouldFailTeamWrite {
+				_, err = svc.RollbackConfigVersion(ctx, 3)
+				checkAuthErr(t, true, err)
+			}
+			if tt.shouldFailGlobalWrite {
+				_, err = svc.RollbackConfigVersion(ctx, 1)
+				checkAuthErr(t, true, err)
+			}
+		})
+	}
+}
+
+func TestDiffConfigVersions(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	versions := map[uint]*fleet.ConfigVersion{
+		1: {ID: 1, Config: json.RawMessage(`{"org_info": {"org_name": "Fleet"}, "smtp_settings": {"password": "old"}}`)},
+		2: {ID: 2, Config: json.RawMessage(`{"org_info": {"org_name": "Acme"}, "smtp_settings": {"password": "new"}}`)},
+		3: {ID: 3, TeamID: ptr.Uint(1), Config: json.RawMessage(`{}`)},
+	}
+	ds.ConfigVersionFunc = func(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+		v := *versions[id]
+		return &v, nil
+	}
+
+	diff, err := svc.DiffConfigVersions(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.ConfigVersionChange{
+		{Path: "org_info.org_name", Old: "Fleet", New: "Acme"},
+		{Path: "smtp_settings.password", Old: fleet.MaskedPassword, New: fleet.MaskedPassword},
+	}, diff.Changes)
+
+	// versions of different configurations cannot be compared
+	_, err = svc.DiffConfigVersions(ctx, 1, 3)
+	require.ErrorContains(t, err, "versions of the same configuration")
+}
+
+func TestRollbackGlobalConfigVersion(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	dsAppConfig := &fleet.AppConfig{
+		OrgInfo:        fleet.OrgInfo{OrgName: "Acme"},
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://localhost:8080"},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return dsAppConfig.Copy(), nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		*dsAppConfig = *conf
+		return nil
+	}
+	ds.ConfigVersionFunc = func(ctx context.Context, id uint) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{
+			ID:     id,
+			Config: json.RawMessage(`{"org_info": {"org_name": "Fleet"}, "server_settings": {"server_url": "https://localhost:8080"}}`),
+		}, nil
+	}
+	var recorded int
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		require.Nil(t, teamID)
+		recorded++
+		return &fleet.ConfigVersion{ID: uint(10 + recorded), Config: json.RawMessage(`{}`)}, nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	restored, err := svc.RollbackConfigVersion(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "Fleet", dsAppConfig.OrgInfo.OrgName)
+	require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	require.Equal(t, uint(10+recorded), restored.ID)
+	require.Equal(t, fleet.ActivityTypeRolledBackConfig{VersionID: 1}, activity)
+}
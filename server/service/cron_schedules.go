@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
@@ -13,3 +16,168 @@ func (svc *Service) TriggerCronSchedule(ctx context.Context, name string) error
 	}
 	return svc.cronSchedulesService.TriggerCronSchedule(name)
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// List cron schedules
+////////////////////////////////////////////////////////////////////////////////
+
+type listCronSchedulesRequest struct{}
+
+type listCronSchedulesResponse struct {
+	Crons []*fleet.CronScheduleStatus `json:"crons"`
+	Err   error                       `json:"error,omitempty"`
+}
+
+func (r listCronSchedulesResponse) error() error { return r.Err }
+
+func listCronSchedulesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	crons, err := svc.ListCronSchedules(ctx)
+	if err != nil {
+		return listCronSchedulesResponse{Err: err}, nil
+	}
+	return listCronSchedulesResponse{Crons: crons}, nil
+}
+
+func (svc *Service) ListCronSchedules(ctx context.Context) ([]*fleet.CronScheduleStatus, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.CronSchedules{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	stats, err := svc.ds.ListLatestCronStats(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list latest cron stats")
+	}
+	pausedNames, err := svc.ds.ListPausedCronSchedules(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list paused cron schedules")
+	}
+	paused := make(map[string]bool, len(pausedNames))
+	for _, name := range pausedNames {
+		paused[name] = true
+	}
+	statsByName := make(map[string][]fleet.CronStats)
+	for _, s := range stats {
+		statsByName[s.Name] = append(statsByName[s.Name], s)
+	}
+
+	intervals := svc.cronSchedulesService.ScheduleIntervals()
+	crons := make([]*fleet.CronScheduleStatus, 0, len(intervals))
+	for name, interval := range intervals {
+		crons = append(crons, newCronScheduleStatus(name, interval, paused[name], statsByName[name]))
+	}
+	sort.Slice(crons, func(i, j int) bool {
+		return crons[i].Name < crons[j].Name
+	})
+	return crons, nil
+}
+
+// newCronScheduleStatus returns the status of the cron schedule from its
+// latest pending and completed runs, as returned by ListLatestCronStats.
+func newCronScheduleStatus(name string, interval time.Duration, paused bool, stats []fleet.CronStats) *fleet.CronScheduleStatus {
+	status := &fleet.CronScheduleStatus{
+		Name:     name,
+		Interval: fleet.Duration{Duration: interval},
+		Paused:   paused,
+	}
+
+	var currentRun, lastRun, lastScheduled *fleet.CronStats
+	for i := range stats {
+		s := &stats[i]
+		if s.StatsType == fleet.CronStatsTypeScheduled && (lastScheduled == nil || s.CreatedAt.After(lastScheduled.CreatedAt)) {
+			lastScheduled = s
+		}
+		switch s.Status {
+		case fleet.CronStatsStatusPending:
+			if currentRun == nil || s.CreatedAt.After(currentRun.CreatedAt) {
+				currentRun = s
+			}
+		case fleet.CronStatsStatusCompleted:
+			if lastRun == nil || s.UpdatedAt.After(lastRun.UpdatedAt) {
+				lastRun = s
+			}
+		}
+	}
+	// a pending run older than the last completed run was abandoned (e.g. the
+	// instance stopped) and will eventually expire.
+	if currentRun != nil && lastRun != nil && currentRun.CreatedAt.Before(lastRun.CreatedAt) {
+		currentRun = nil
+	}
+
+	if currentRun != nil {
+		status.CurrentRun = fleet.NewCronScheduleRun(*currentRun)
+	}
+	if lastRun != nil {
+		status.LastRun = fleet.NewCronScheduleRun(*lastRun)
+	}
+	if lastScheduled != nil && !paused && interval > 0 {
+		next := lastScheduled.CreatedAt.Add(interval)
+		status.NextRunAt = &next
+	}
+	return status
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Trigger cron schedule
+////////////////////////////////////////////////////////////////////////////////
+
+type triggerCronScheduleRequest struct {
+	Name string `url:"name"`
+}
+
+type triggerCronScheduleResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r triggerCronScheduleResponse) error() error { return r.Err }
+
+func triggerCronScheduleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*triggerCronScheduleRequest)
+	if err := svc.TriggerCronSchedule(ctx, req.Name); err != nil {
+		return triggerCronScheduleResponse{Err: err}, nil
+	}
+	return triggerCronScheduleResponse{}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Pause and resume cron schedule
+////////////////////////////////////////////////////////////////////////////////
+
+type pauseCronScheduleRequest struct {
+	Name string `url:"name"`
+}
+
+type pauseCronScheduleResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r pauseCronScheduleResponse) error() error { return r.Err }
+
+func pauseCronScheduleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*pauseCronScheduleRequest)
+	if err := svc.PauseCronSchedule(ctx, req.Name, true); err != nil {
+		return pauseCronScheduleResponse{Err: err}, nil
+	}
+	return pauseCronScheduleResponse{}, nil
+}
+
+func resumeCronScheduleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*pauseCronScheduleRequest)
+	if err := svc.PauseCronSchedule(ctx, req.Name, false); err != nil {
+		return pauseCronScheduleResponse{Err: err}, nil
+	}
+	return pauseCronScheduleResponse{}, nil
+}
+
+func (svc *Service) PauseCronSchedule(ctx context.Context, name string, paused bool) error {
+	if err := svc.authz.Authorize(ctx, &fleet.CronSchedules{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if _, ok := svc.cronSchedulesService.ScheduleIntervals()[name]; !ok {
+		return ctxerr.Wrap(ctx, newNotFoundError(), "cron schedule "+name)
+	}
+	if err := svc.ds.SetCronSchedulePaused(ctx, name, paused); err != nil {
+		return ctxerr.Wrap(ctx, err, "set cron schedule paused")
+	}
+	return nil
+}
@@ -17,6 +17,15 @@ import (
 
 func TestTriggerCronScheduleAuth(t *testing.T) {
 	ds := new(mock.Store)
+	ds.ListLatestCronStatsFunc = func(ctx context.Context) ([]fleet.CronStats, error) {
+		return nil, nil
+	}
+	ds.ListPausedCronSchedulesFunc = func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	}
+	ds.SetCronSchedulePausedFunc = func(ctx context.Context, name string, paused bool) error {
+		return nil
+	}
 
 	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{StartCronSchedules: []TestNewScheduleFunc{
 		func(ctx context.Context, ds fleet.Datastore) fleet.NewCronScheduleFunc {
@@ -84,6 +93,12 @@ func TestTriggerCronScheduleAuth(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 			}
+
+			_, err = svc.ListCronSchedules(ctx)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			err = svc.PauseCronSchedule(ctx, "test_sched", true)
+			checkAuthErr(t, tt.shouldFail, err)
 		})
 	}
 }
@@ -140,3 +155,95 @@ func TestCronSchedulesService(t *testing.T) {
 	time.Sleep(1500 * time.Millisecond)
 	require.Equal(t, uint32(3), atomic.LoadUint32(&jobsDone)) // 2 regularly scheduled (at 3s and 6s) plus 1 triggered
 }
+
+func TestListAndPauseCronSchedules(t *testing.T) {
+	ds := new(mock.Store)
+	newSched := func(name string, interval time.Duration) TestNewScheduleFunc {
+		return func(ctx context.Context, ds fleet.Datastore) fleet.NewCronScheduleFunc {
+			return func() (fleet.CronSchedule, error) {
+				return schedule.New(ctx, name, "id", interval, schedule.NopLocker{}, schedule.NopStatsStore{}), nil
+			}
+		}
+	}
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{StartCronSchedules: []TestNewScheduleFunc{
+		newSched("sched_b", time.Hour),
+		newSched("sched_a", 10*time.Minute),
+	}})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ds.ListLatestCronStatsFunc = func(ctx context.Context) ([]fleet.CronStats, error) {
+		return []fleet.CronStats{
+			// sched_a has a run in progress
+			{Name: "sched_a", StatsType: fleet.CronStatsTypeScheduled, Status: fleet.CronStatsStatusCompleted, Instance: "id", CreatedAt: now.Add(-12 * time.Minute), UpdatedAt: now.Add(-11 * time.Minute), Errors: fleet.CronScheduleErrors{"job": "boom"}},
+			{Name: "sched_a", StatsType: fleet.CronStatsTypeScheduled, Status: fleet.CronStatsStatusPending, Instance: "id", CreatedAt: now.Add(-2 * time.Minute), UpdatedAt: now.Add(-2 * time.Minute)},
+			// sched_b was triggered after the scheduled run, and has an
+			// abandoned pending run
+			{Name: "sched_b", StatsType: fleet.CronStatsTypeScheduled, Status: fleet.CronStatsStatusPending, Instance: "id", CreatedAt: now.Add(-3 * time.Hour), UpdatedAt: now.Add(-3 * time.Hour)},
+			{Name: "sched_b", StatsType: fleet.CronStatsTypeScheduled, Status: fleet.CronStatsStatusCompleted, Instance: "id", CreatedAt: now.Add(-30 * time.Minute), UpdatedAt: now.Add(-29 * time.Minute)},
+			{Name: "sched_b", StatsType: fleet.CronStatsTypeTriggered, Status: fleet.CronStatsStatusCompleted, Instance: "id", CreatedAt: now.Add(-5 * time.Minute), UpdatedAt: now.Add(-4 * time.Minute)},
+			// not registered with this instance
+			{Name: "sched_c", StatsType: fleet.CronStatsTypeScheduled, Status: fleet.CronStatsStatusCompleted, Instance: "id", CreatedAt: now, UpdatedAt: now},
+		}, nil
+	}
+	paused := map[string]bool{}
+	ds.ListPausedCronSchedulesFunc = func(ctx context.Context) ([]string, error) {
+		var names []string
+		for name := range paused {
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	ds.SetCronSchedulePausedFunc = func(ctx context.Context, name string, p bool) error {
+		if p {
+			paused[name] = true
+		} else {
+			delete(paused, name)
+		}
+		return nil
+	}
+
+	crons, err := svc.ListCronSchedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, crons, 2)
+
+	a := crons[0]
+	require.Equal(t, "sched_a", a.Name)
+	require.Equal(t, 10*time.Minute, a.Interval.Duration)
+	require.False(t, a.Paused)
+	require.NotNil(t, a.CurrentRun)
+	require.Equal(t, fleet.CronStatsStatusPending, a.CurrentRun.Status)
+	require.Nil(t, a.CurrentRun.FinishedAt)
+	require.NotNil(t, a.LastRun)
+	require.Equal(t, now.Add(-12*time.Minute), a.LastRun.StartedAt)
+	require.Equal(t, 60.0, *a.LastRun.DurationSeconds)
+	require.Equal(t, fleet.CronScheduleErrors{"job": "boom"}, a.LastRun.Errors)
+	require.Equal(t, now.Add(8*time.Minute), *a.NextRunAt)
+
+	b := crons[1]
+	require.Equal(t, "sched_b", b.Name)
+	require.Nil(t, b.CurrentRun)
+	require.NotNil(t, b.LastRun)
+	require.Equal(t, fleet.CronStatsTypeTriggered, b.LastRun.Type)
+	require.Equal(t, now.Add(30*time.Minute), *b.NextRunAt)
+
+	// pause sched_b
+	require.NoError(t, svc.PauseCronSchedule(ctx, "sched_b", true))
+	crons, err = svc.ListCronSchedules(ctx)
+	require.NoError(t, err)
+	require.False(t, crons[0].Paused)
+	require.True(t, crons[1].Paused)
+	require.Nil(t, crons[1].NextRunAt)
+
+	// resume sched_b
+	require.NoError(t, svc.PauseCronSchedule(ctx, "sched_b", false))
+	crons, err = svc.ListCronSchedules(ctx)
+	require.NoError(t, err)
+	require.False(t, crons[1].Paused)
+
+	// unknown schedule
+	ds.SetCronSchedulePausedFuncInvoked = false
+	err = svc.PauseCronSchedule(ctx, "sched_c", true)
+	require.True(t, fleet.IsNotFound(err))
+	require.False(t, ds.SetCronSchedulePausedFuncInvoked)
+}
@@ -0,0 +1,585 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/spreadsheet"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// dataExportFileResponse renders the generated file of an export.
+type dataExportFileResponse struct {
+	DataExport *fleet.DataExport `json:"-"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r dataExportFileResponse) error() error { return r.Err }
+
+func (r dataExportFileResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, r.DataExport.Filename))
+	w.Header().Set("Content-Type", r.DataExport.Format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(r.DataExport.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(r.DataExport.Data); err != nil {
+		logging.WithErr(ctx, err)
+	}
+}
+
+// dataExportAcceptedResponse is returned when the export is generated in the
+// background.
+type dataExportAcceptedResponse struct {
+	DataExport *fleet.DataExport `json:"data_export"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r dataExportAcceptedResponse) error() error { return r.Err }
+func (r dataExportAcceptedResponse) Status() int  { return http.StatusAccepted }
+
+// dataExportResponse returns the file of the export if it was generated
+// synchronously, or the pending export otherwise.
+func dataExportResponse(export *fleet.DataExport) errorer {
+	if export.Status == fleet.DataExportStatusPending {
+		return dataExportAcceptedResponse{DataExport: export}
+	}
+	return dataExportFileResponse{DataExport: export}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Export hosts
+////////////////////////////////////////////////////////////////////////////////
+
+type exportHostsRequest struct {
+	Opts    fleet.HostListOptions `url:"host_options"`
+	LabelID *uint                 `query:"label_id,optional"`
+	Format  string                `query:"format"`
+	Columns string                `query:"columns,optional"`
+}
+
+func exportHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*exportHostsRequest)
+
+	opts := fleet.HostExportOptions{ListOptions: req.Opts, LabelID: req.LabelID}
+	for _, col := range strings.Split(req.Columns, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			opts.Columns = append(opts.Columns, col)
+		}
+	}
+
+	export, err := svc.ExportHosts(ctx, opts, fleet.DataExportFormat(req.Format))
+	if err != nil {
+		return dataExportFileResponse{Err: err}, nil
+	}
+	return dataExportResponse(export), nil
+}
+
+func (svc *Service) ExportHosts(ctx context.Context, opts fleet.HostExportOptions, format fleet.DataExportFormat) (*fleet.DataExport, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	if opts.LabelID != nil {
+		if err := svc.authz.Authorize(ctx, &fleet.Label{}, fleet.ActionRead); err != nil {
+			return nil, err
+		}
+	}
+
+	if !license.IsPremium(ctx) {
+		// the low disk space and bootstrap package filters are premium-only
+		opts.ListOptions.LowDiskSpaceFilter = nil
+		opts.ListOptions.MDMBootstrapPackageFilter = nil
+	}
+
+	opts.ListOptions.AdditionalFilters = nil
+	opts.ListOptions.Page = 0
+	opts.ListOptions.PerPage = 0 // explicitly disable any limit, we want all matching hosts
+	opts.ListOptions.After = ""
+	opts.ListOptions.IncludeMetadata = false
+	opts.ListOptions.PopulateSoftware = false
+	opts.ListOptions.PopulatePolicies = false
+	// no column means all columns, including the device mapping
+	opts.ListOptions.DeviceMapping = len(opts.Columns) == 0
+	for _, col := range opts.Columns {
+		if col == "device_mapping" {
+			opts.ListOptions.DeviceMapping = true
+		}
+	}
+
+	return svc.newDataExport(ctx, fleet.DataExportResourceHosts, format, opts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Export software
+////////////////////////////////////////////////////////////////////////////////
+
+type exportSoftwareRequest struct {
+	fleet.SoftwareListOptions
+	Format string `query:"format"`
+}
+
+func exportSoftwareEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*exportSoftwareRequest)
+	export, err := svc.ExportSoftware(ctx, req.SoftwareListOptions, fleet.DataExportFormat(req.Format))
+	if err != nil {
+		return dataExportFileResponse{Err: err}, nil
+	}
+	return dataExportResponse(export), nil
+}
+
+func (svc *Service) ExportSoftware(ctx context.Context, opts fleet.SoftwareListOptions, format fleet.DataExportFormat) (*fleet.DataExport, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AuthzSoftwareInventory{
+		TeamID: opts.TeamID,
+	}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	// same default sort order as the software list
+	if opts.ListOptions.OrderKey == "" {
+		opts.ListOptions.OrderKey = "hosts_count"
+		opts.ListOptions.OrderDirection = fleet.OrderDescending
+	}
+	opts.WithHostCounts = true
+	opts.HostID = nil
+	opts.ListOptions.Page = 0
+	opts.ListOptions.PerPage = 0
+	opts.ListOptions.After = ""
+	opts.ListOptions.IncludeMetadata = false
+
+	return svc.newDataExport(ctx, fleet.DataExportResourceSoftware, format, opts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Export vulnerabilities
+////////////////////////////////////////////////////////////////////////////////
+
+type exportVulnerabilitiesRequest struct {
+	fleet.VulnListOptions
+	Format string `query:"format"`
+}
+
+func exportVulnerabilitiesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*exportVulnerabilitiesRequest)
+	export, err := svc.ExportVulnerabilities(ctx, req.VulnListOptions, fleet.DataExportFormat(req.Format))
+	if err != nil {
+		return dataExportFileResponse{Err: err}, nil
+	}
+	return dataExportResponse(export), nil
+}
+
+func (svc *Service) ExportVulnerabilities(ctx context.Context, opts fleet.VulnListOptions, format fleet.DataExportFormat) (*fleet.DataExport, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AuthzSoftwareInventory{
+		TeamID: &opts.TeamID,
+	}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if len(opts.ValidSortColumns) == 0 {
+		opts.ValidSortColumns = freeValidVulnSortColumns
+	}
+	if !opts.HasValidSortColumn() {
+		return nil, badRequest("invalid order key")
+	}
+	if opts.KnownExploit && !opts.IsEE {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	opts.ListOptions.Page = 0
+	opts.ListOptions.PerPage = 0
+	opts.ListOptions.After = ""
+	opts.ListOptions.IncludeMetadata = false
+
+	return svc.newDataExport(ctx, fleet.DataExportResourceVulnerabilities, format, opts)
+}
+
+// newDataExport generates the export synchronously if it has at most
+// fleet.DataExportMaxSyncRows rows, otherwise it saves it to be generated in
+// the background. The caller must have authorized the export.
+func (svc *Service) newDataExport(ctx context.Context, resource fleet.DataExportResource, format fleet.DataExportFormat, opts interface{}) (*fleet.DataExport, error) {
+	if !format.IsValid() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("format", "unsupported or unspecified export format").
+			WithStatus(http.StatusUnsupportedMediaType))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	rawOpts, err := json.Marshal(opts)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal export options")
+	}
+
+	now := time.Now().UTC()
+	export := &fleet.DataExport{
+		UserID:   vc.UserID(),
+		Resource: resource,
+		Format:   format,
+		Status:   fleet.DataExportStatusPending,
+		Filename: fleet.NewDataExportFilename(resource, format, now),
+		Options:  rawOpts,
+	}
+
+	data, err := generateDataExport(ctx, svc.ds, vc.User, export, fleet.DataExportMaxSyncRows)
+	if errors.Is(err, errDataExportTooLarge) {
+		return svc.ds.NewDataExport(ctx, export)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	export.Status = fleet.DataExportStatusCompleted
+	export.Data = data
+	export.Size = int64(len(data))
+	export.CreatedAt = now
+	export.CompletedAt = &now
+	return export, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get data export
+////////////////////////////////////////////////////////////////////////////////
+
+type getDataExportRequest struct {
+	ID uint `url:"id"`
+}
+
+type getDataExportResponse struct {
+	DataExport *fleet.DataExport `json:"data_export,omitempty"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r getDataExportResponse) error() error { return r.Err }
+
+func getDataExportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getDataExportRequest)
+	export, err := svc.GetDataExport(ctx, req.ID)
+	if err != nil {
+		return getDataExportResponse{Err: err}, nil
+	}
+	return getDataExportResponse{DataExport: export}, nil
+}
+
+func (svc *Service) GetDataExport(ctx context.Context, id uint) (*fleet.DataExport, error) {
+	export, err := svc.ds.DataExport(ctx, id)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			svc.authz.SkipAuthorization(ctx)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get data export")
+	}
+
+	if err := svc.authz.Authorize(ctx, export, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Download data export
+////////////////////////////////////////////////////////////////////////////////
+
+type downloadDataExportRequest struct {
+	ID uint `url:"id"`
+}
+
+func downloadDataExportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*downloadDataExportRequest)
+	export, err := svc.DownloadDataExport(ctx, req.ID)
+	if err != nil {
+		return dataExportFileResponse{Err: err}, nil
+	}
+	return dataExportFileResponse{DataExport: export}, nil
+}
+
+func (svc *Service) DownloadDataExport(ctx context.Context, id uint) (*fleet.DataExport, error) {
+	export, err := svc.GetDataExport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch export.Status {
+	case fleet.DataExportStatusPending:
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "the export is not completed yet"})
+	case fleet.DataExportStatusFailed:
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "the export failed"})
+	}
+
+	data, err := svc.ds.DataExportContent(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get data export content")
+	}
+	export.Data = data
+	return export, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Export generation
+////////////////////////////////////////////////////////////////////////////////
+
+var errDataExportTooLarge = errors.New("too many rows to generate the export synchronously")
+
+// dataExportBatchSize is the number of rows loaded at once when the rows of
+// an export generated in the background are streamed from the datastore.
+const dataExportBatchSize = 1000
+
+// generateDataExport generates the file of the export on behalf of the user.
+// If maxRows is > 0 and the export has more rows, it returns
+// errDataExportTooLarge. Otherwise (i.e. when generated in the background),
+// the rows are streamed from the datastore and written to the file in
+// batches, so that they are never all loaded in memory.
+func generateDataExport(ctx context.Context, ds fleet.Datastore, user *fleet.User, export *fleet.DataExport, maxRows int) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   spreadsheet.Writer
+		err error
+	)
+	switch export.Format {
+	case fleet.DataExportFormatCSV:
+		w = spreadsheet.NewCSVWriter(&buf)
+	case fleet.DataExportFormatXLSX:
+		if w, err = spreadsheet.NewXLSXWriter(&buf, export.Resource.Title()); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "create xlsx writer")
+		}
+	default:
+		return nil, ctxerr.Errorf(ctx, "unsupported export format: %s", export.Format)
+	}
+
+	write := func(records [][]string) error {
+		for _, rec := range records {
+			if err := w.Write(rec); err != nil {
+				return ctxerr.Wrap(ctx, err, "write export record")
+			}
+		}
+		return nil
+	}
+
+	switch export.Resource {
+	case fleet.DataExportResourceHosts:
+		err = writeHostsDataExport(ctx, ds, user, export.Options, maxRows, write)
+	case fleet.DataExportResourceSoftware:
+		err = writeSoftwareDataExport(ctx, ds, export.Options, maxRows, write)
+	case fleet.DataExportResourceVulnerabilities:
+		err = writeVulnerabilitiesDataExport(ctx, ds, export.Options, maxRows, write)
+	default:
+		return nil, ctxerr.Errorf(ctx, "unsupported export resource: %s", export.Resource)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "close export writer")
+	}
+	return buf.Bytes(), nil
+}
+
+// dataExportLimit returns the page size to use to list the rows of an export
+// limited to maxRows, so that a larger export can be detected.
+func dataExportLimit(maxRows int) uint {
+	if maxRows <= 0 {
+		return 0
+	}
+	return uint(maxRows) + 1
+}
+
+func writeHostsDataExport(ctx context.Context, ds fleet.Datastore, user *fleet.User, rawOpts json.RawMessage, maxRows int, write func([][]string) error) error {
+	var opts fleet.HostExportOptions
+	if err := json.Unmarshal(rawOpts, &opts); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal hosts export options")
+	}
+	opts.ListOptions.PerPage = dataExportLimit(maxRows)
+	opts.ListOptions.PopulateCustomFields = true
+	opts.ListOptions.PopulateWarranty = true
+
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config for hosts export")
+	}
+
+	// writeHosts writes the records of a batch of hosts, the header is only
+	// written for the first batch.
+	withHeader := true
+	writeHosts := func(hosts []*fleet.Host) error {
+		hostResps := make([]*fleet.HostResponse, len(hosts))
+		for i, h := range hosts {
+			hostResps[i] = fleet.HostResponseForHostCheap(h)
+		}
+		records, err := hostsCSVRecords(ctx, hostResps, opts.Columns, appConfig.HostCustomFields)
+		if err != nil {
+			return err
+		}
+		if !withHeader && len(records) > 0 {
+			records = records[1:]
+		}
+		withHeader = false
+		return write(records)
+	}
+
+	filter := fleet.TeamFilter{User: user, IncludeObserver: true}
+
+	if maxRows <= 0 && opts.LabelID == nil {
+		if err := ds.StreamHosts(ctx, filter, opts.ListOptions, dataExportBatchSize, writeHosts); err != nil {
+			return ctxerr.Wrap(ctx, err, "stream hosts to export")
+		}
+		if withHeader {
+			// no host was streamed, write the header only
+			return writeHosts(nil)
+		}
+		return nil
+	}
+
+	var hosts []*fleet.Host
+	if opts.LabelID == nil {
+		hosts, err = ds.ListHosts(ctx, filter, opts.ListOptions)
+	} else {
+		hosts, err = ds.ListHostsInLabel(ctx, filter, *opts.LabelID, opts.ListOptions)
+	}
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list hosts to export")
+	}
+	if maxRows > 0 && len(hosts) > maxRows {
+		return errDataExportTooLarge
+	}
+	return writeHosts(hosts)
+}
+
+func writeSoftwareDataExport(ctx context.Context, ds fleet.Datastore, rawOpts json.RawMessage, maxRows int, write func([][]string) error) error {
+	var opts fleet.SoftwareListOptions
+	if err := json.Unmarshal(rawOpts, &opts); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal software export options")
+	}
+	opts.ListOptions.PerPage = dataExportLimit(maxRows)
+
+	header := []string{"name", "version", "source", "browser", "bundle_identifier", "vendor", "hosts_count", "vulnerabilities"}
+	if err := write([][]string{header}); err != nil {
+		return err
+	}
+	writeSoftware := func(software []fleet.Software) error {
+		records := make([][]string, 0, len(software))
+		for _, sw := range software {
+			cves := make([]string, 0, len(sw.Vulnerabilities))
+			for _, vuln := range sw.Vulnerabilities {
+				cves = append(cves, vuln.CVE)
+			}
+			records = append(records, []string{
+				sw.Name,
+				sw.Version,
+				sw.Source,
+				sw.Browser,
+				sw.BundleIdentifier,
+				sw.Vendor,
+				strconv.Itoa(sw.HostsCount),
+				strings.Join(cves, ", "),
+			})
+		}
+		return write(records)
+	}
+
+	if maxRows <= 0 {
+		if err := ds.StreamSoftware(ctx, opts, dataExportBatchSize, writeSoftware); err != nil {
+			return ctxerr.Wrap(ctx, err, "stream software to export")
+		}
+		return nil
+	}
+
+	software, _, err := ds.ListSoftware(ctx, opts)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list software to export")
+	}
+	if len(software) > maxRows {
+		return errDataExportTooLarge
+	}
+	return writeSoftware(software)
+}
+
+func writeVulnerabilitiesDataExport(ctx context.Context, ds fleet.Datastore, rawOpts json.RawMessage, maxRows int, write func([][]string) error) error {
+	var opts fleet.VulnListOptions
+	if err := json.Unmarshal(rawOpts, &opts); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal vulnerabilities export options")
+	}
+	opts.ListOptions.PerPage = dataExportLimit(maxRows)
+
+	vulns, _, err := ds.ListVulnerabilities(ctx, opts)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list vulnerabilities to export")
+	}
+	if maxRows > 0 && len(vulns) > maxRows {
+		return errDataExportTooLarge
+	}
+
+	header := []string{"cve", "hosts_count", "created_at", "details_link"}
+	if opts.IsEE {
+		header = append(header, "cvss_score", "epss_probability", "cisa_known_exploit", "cve_published")
+	}
+	records := make([][]string, 0, len(vulns)+1)
+	records = append(records, header)
+	for _, vuln := range vulns {
+		rec := []string{
+			vuln.CVE.CVE,
+			strconv.FormatUint(uint64(vuln.HostsCount), 10),
+			vuln.CreatedAt.UTC().Format(time.RFC3339),
+			vulnerabilityDetailsLink(vuln),
+		}
+		if opts.IsEE {
+			var cvss, epss, exploit, published string
+			if vuln.CVSSScore != nil && *vuln.CVSSScore != nil {
+				cvss = strconv.FormatFloat(**vuln.CVSSScore, 'f', -1, 64)
+			}
+			if vuln.EPSSProbability != nil && *vuln.EPSSProbability != nil {
+				epss = strconv.FormatFloat(**vuln.EPSSProbability, 'f', -1, 64)
+			}
+			if vuln.CISAKnownExploit != nil && *vuln.CISAKnownExploit != nil {
+				exploit = strconv.FormatBool(**vuln.CISAKnownExploit)
+			}
+			if vuln.CVEPublished != nil && *vuln.CVEPublished != nil {
+				published = (**vuln.CVEPublished).UTC().Format(time.RFC3339)
+			}
+			rec = append(rec, cvss, epss, exploit, published)
+		}
+		records = append(records, rec)
+	}
+	return write(records)
+}
+
+// ProcessDataExports generates the pending exports on behalf of the users
+// that requested them.
+func ProcessDataExports(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	exports, err := ds.ListPendingDataExports(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list pending data exports")
+	}
+
+	for _, export := range exports {
+		user, err := ds.UserByID(ctx, export.UserID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get data export user")
+		}
+
+		var exportErr string
+		data, err := generateDataExport(ctx, ds, user, export, 0)
+		if err != nil {
+			level.Error(logger).Log("msg", "generate data export", "export_id", export.ID, "err", err)
+			exportErr = "failed to generate the export"
+		}
+		if err := ds.CompleteDataExport(ctx, export.ID, data, exportErr); err != nil {
+			return ctxerr.Wrap(ctx, err, "complete data export")
+		}
+		level.Debug(logger).Log("msg", "processed data export", "export_id", export.ID, "size", len(data))
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		return nil, nil, nil
+	}
+	ds.DataExportFunc = func(ctx context.Context, id uint) (*fleet.DataExport, error) {
+		return &fleet.DataExport{ID: id, UserID: id, Status: fleet.DataExportStatusPending}, nil
+	}
+
+	testCases := []struct {
+		name                string
+		user                *fleet.User
+		shouldFailTeamRead  bool
+		shouldFailGlobal    bool
+		shouldFailOthersGet bool
+	}{
+		{
+			name:                "global admin",
+			user:                &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)},
+			shouldFailTeamRead:  false,
+			shouldFailGlobal:    false,
+			shouldFailOthersGet: true,
+		},
+		{
+			name:                "global observer",
+			user:                &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailTeamRead:  false,
+			shouldFailGlobal:    false,
+			shouldFailOthersGet: true,
+		},
+		{
+			name:                "team observer, belongs to team",
+			user:                &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailTeamRead:  false,
+			shouldFailGlobal:    true,
+			shouldFailOthersGet: true,
+		},
+		{
+			name:                "team admin, DOES NOT belong to team",
+			user:                &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailTeamRead:  true,
+			shouldFailGlobal:    true,
+			shouldFailOthersGet: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ExportSoftware(ctx, fleet.SoftwareListOptions{TeamID: ptr.Uint(1)}, fleet.DataExportFormatCSV)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ExportSoftware(ctx, fleet.SoftwareListOptions{}, fleet.DataExportFormatCSV)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+
+			_, err = svc.GetDataExport(ctx, tt.user.ID)
+			checkAuthErr(t, false, err)
+
+			_, err = svc.GetDataExport(ctx, tt.user.ID+1)
+			checkAuthErr(t, tt.shouldFailOthersGet, err)
+		})
+	}
+}
+
+func TestExportHosts(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	var hostsCount int
+	ds.ListHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error) {
+		require.EqualValues(t, fleet.DataExportMaxSyncRows+1, opt.PerPage)
+		hosts := make([]*fleet.Host, hostsCount)
+		for i := range hosts {
+			hosts[i] = &fleet.Host{ID: uint(i + 1), Hostname: "host" + string(rune('a'+i%26))}
+		}
+		return hosts, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	var savedExport *fleet.DataExport
+	ds.NewDataExportFunc = func(ctx context.Context, export *fleet.DataExport) (*fleet.DataExport, error) {
+		savedExport = export
+		return &fleet.DataExport{ID: 1, UserID: export.UserID, Status: export.Status, Options: export.Options}, nil
+	}
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := svc.ExportHosts(ctx, fleet.HostExportOptions{}, "pdf")
+		require.Error(t, err)
+		var sc interface{ Status() int }
+		require.ErrorAs(t, err, &sc)
+		require.Equal(t, http.StatusUnsupportedMediaType, sc.Status())
+	})
+
+	t.Run("synchronous", func(t *testing.T) {
+		hostsCount = 2
+		export, err := svc.ExportHosts(ctx, fleet.HostExportOptions{Columns: []string{"id", "hostname"}}, fleet.DataExportFormatCSV)
+		require.NoError(t, err)
+		require.Equal(t, fleet.DataExportStatusCompleted, export.Status)
+		require.Equal(t, "id,hostname\n1,hosta\n2,hostb\n", string(export.Data))
+		require.EqualValues(t, len(export.Data), export.Size)
+		require.False(t, ds.NewDataExportFuncInvoked)
+	})
+
+	t.Run("invalid column", func(t *testing.T) {
+		_, err := svc.ExportHosts(ctx, fleet.HostExportOptions{Columns: []string{"nope"}}, fleet.DataExportFormatCSV)
+		var bre *fleet.BadRequestError
+		require.ErrorAs(t, err, &bre)
+	})
+
+	t.Run("asynchronous", func(t *testing.T) {
+		hostsCount = fleet.DataExportMaxSyncRows + 1
+		export, err := svc.ExportHosts(ctx, fleet.HostExportOptions{
+			ListOptions: fleet.HostListOptions{ListOptions: fleet.ListOptions{Page: 2, PerPage: 10}},
+		}, fleet.DataExportFormatXLSX)
+		require.NoError(t, err)
+		require.True(t, ds.NewDataExportFuncInvoked)
+		require.Equal(t, fleet.DataExportStatusPending, export.Status)
+		require.Nil(t, export.Data)
+
+		require.Equal(t, fleet.DataExportResourceHosts, savedExport.Resource)
+		require.Equal(t, fleet.DataExportFormatXLSX, savedExport.Format)
+		var opts fleet.HostExportOptions
+		require.NoError(t, json.Unmarshal(savedExport.Options, &opts))
+		require.Zero(t, opts.ListOptions.Page)
+		require.Zero(t, opts.ListOptions.PerPage)
+		require.True(t, opts.ListOptions.DeviceMapping)
+	})
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}})
+
+	var status fleet.DataExportStatus
+	ds.DataExportFunc = func(ctx context.Context, id uint) (*fleet.DataExport, error) {
+		return &fleet.DataExport{ID: id, UserID: 1, Status: status, Format: fleet.DataExportFormatCSV}, nil
+	}
+	ds.DataExportContentFunc = func(ctx context.Context, id uint) ([]byte, error) {
+		return []byte("a,b\n"), nil
+	}
+
+	var bre *fleet.BadRequestError
+	status = fleet.DataExportStatusPending
+	_, err := svc.DownloadDataExport(ctx, 1)
+	require.ErrorAs(t, err, &bre)
+
+	status = fleet.DataExportStatusFailed
+	_, err = svc.DownloadDataExport(ctx, 1)
+	require.ErrorAs(t, err, &bre)
+
+	status = fleet.DataExportStatusCompleted
+	export, err := svc.DownloadDataExport(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n", string(export.Data))
+}
+
+func TestProcessDataExports(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	opts, err := json.Marshal(fleet.SoftwareListOptions{})
+	require.NoError(t, err)
+	ds.ListPendingDataExportsFunc = func(ctx context.Context) ([]*fleet.DataExport, error) {
+		return []*fleet.DataExport{
+			{ID: 1, UserID: 1, Resource: fleet.DataExportResourceSoftware, Format: fleet.DataExportFormatCSV, Options: opts},
+			{ID: 2, UserID: 1, Resource: fleet.DataExportResourceSoftware, Format: fleet.DataExportFormatXLSX, Options: opts},
+			{ID: 3, UserID: 1, Resource: "unknown", Format: fleet.DataExportFormatCSV, Options: opts},
+		}, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, GlobalRole: ptr.String(fleet.RoleAdmin)}, nil
+	}
+	ds.StreamSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions, batchSize int, fn func([]fleet.Software) error) error {
+		require.Equal(t, dataExportBatchSize, batchSize)
+		return fn([]fleet.Software{
+			{Name: "foo", Version: "1.0", Source: "apps", HostsCount: 3, Vulnerabilities: fleet.Vulnerabilities{{CVE: "CVE-1"}, {CVE: "CVE-2"}}},
+		})
+	}
+	completed := make(map[uint][]byte)
+	failed := make(map[uint]string)
+	ds.CompleteDataExportFunc = func(ctx context.Context, id uint, data []byte, exportErr string) error {
+		if exportErr != "" {
+			failed[id] = exportErr
+			return nil
+		}
+		completed[id] = data
+		return nil
+	}
+
+	require.NoError(t, ProcessDataExports(ctx, ds, kitlog.NewNopLogger()))
+	require.Len(t, completed, 2)
+	require.Equal(t, "name,version,source,browser,bundle_identifier,vendor,hosts_count,vulnerabilities\nfoo,1.0,apps,,,,3,\"CVE-1, CVE-2\"\n", string(completed[1]))
+	require.Equal(t, "PK", string(completed[2][:2]))
+	require.Len(t, failed, 1)
+	require.Contains(t, failed, uint(3))
+}
+
+func TestProcessDataExportsStreamsHosts(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	opts, err := json.Marshal(fleet.HostExportOptions{Columns: []string{"id", "hostname"}})
+	require.NoError(t, err)
+	ds.ListPendingDataExportsFunc = func(ctx context.Context) ([]*fleet.DataExport, error) {
+		return []*fleet.DataExport{
+			{ID: 1, UserID: 1, Resource: fleet.DataExportResourceHosts, Format: fleet.DataExportFormatCSV, Options: opts},
+		}, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, GlobalRole: ptr.String(fleet.RoleAdmin)}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	var batches [][]*fleet.Host
+	ds.StreamHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions, batchSize int, fn func([]*fleet.Host) error) error {
+		require.Equal(t, dataExportBatchSize, batchSize)
+		for _, batch := range batches {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var completed []byte
+	ds.CompleteDataExportFunc = func(ctx context.Context, id uint, data []byte, exportErr string) error {
+		require.Empty(t, exportErr)
+		completed = data
+		return nil
+	}
+
+	// the header is written once for all the batches
+	batches = [][]*fleet.Host{
+		{{ID: 1, Hostname: "a"}, {ID: 2, Hostname: "b"}},
+		{{ID: 3, Hostname: "c"}},
+	}
+	require.NoError(t, ProcessDataExports(ctx, ds, kitlog.NewNopLogger()))
+	require.Equal(t, "id,hostname\n1,a\n2,b\n3,c\n", string(completed))
+	require.False(t, ds.ListHostsFuncInvoked)
+
+	// the header is written even if there is no host
+	batches = nil
+	require.NoError(t, ProcessDataExports(ctx, ds, kitlog.NewNopLogger()))
+	require.Equal(t, "id,hostname\n", string(completed))
+}
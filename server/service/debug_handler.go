@@ -12,6 +12,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/token"
 	"github.com/fleetdm/fleet/v4/server/errorstore"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/latencybudget"
 
 	kitlog "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -79,7 +80,10 @@ func jsonHandler(
 }
 
 // MakeDebugHandler creates an HTTP handler for the Fleet debug endpoints.
-func MakeDebugHandler(svc fleet.Service, config config.FleetConfig, logger kitlog.Logger, eh *errorstore.Handler, ds fleet.Datastore) http.Handler {
+func MakeDebugHandler(
+	svc fleet.Service, config config.FleetConfig, logger kitlog.Logger, eh *errorstore.Handler, ds fleet.Datastore,
+	lb *latencybudget.Middleware,
+) http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -91,6 +95,14 @@ func MakeDebugHandler(svc fleet.Service, config config.FleetConfig, logger kitlo
 	r.HandleFunc("/debug/db/locks", jsonHandler(logger, func(ctx context.Context) (interface{}, error) { return ds.DBLocks(ctx) }))
 	r.HandleFunc("/debug/db/innodb-status", jsonHandler(logger, func(ctx context.Context) (interface{}, error) { return ds.InnoDBStatus(ctx) }))
 	r.HandleFunc("/debug/db/process-list", jsonHandler(logger, func(ctx context.Context) (interface{}, error) { return ds.ProcessList(ctx) }))
+	r.HandleFunc("/debug/db/table-growth", jsonHandler(logger, func(ctx context.Context) (interface{}, error) { return ds.ListTableGrowth(ctx) }))
+	r.HandleFunc("/debug/db/maintenance", jsonHandler(logger, func(ctx context.Context) (interface{}, error) { return ds.ListDatabaseMaintenanceRuns(ctx) }))
+	r.HandleFunc("/debug/slow-endpoints", jsonHandler(logger, func(ctx context.Context) (interface{}, error) {
+		if lb == nil {
+			return []latencybudget.EndpointReport{}, nil
+		}
+		return lb.Report(), nil
+	}))
 
 	mw := &debugAuthenticationMiddleware{
 		service: svc,
@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/latencybudget"
+	kitlog "github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -39,7 +42,7 @@ var testConfig = config.FleetConfig{
 }
 
 func TestDebugHandlerAuthenticationTokenMissing(t *testing.T) {
-	handler := MakeDebugHandler(&mockService{}, testConfig, nil, nil, nil)
+	handler := MakeDebugHandler(&mockService{}, testConfig, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "https://fleetdm.com/debug/pprof/profile", nil)
 	res := httptest.NewRecorder()
@@ -56,7 +59,7 @@ func TestDebugHandlerAuthenticationSessionInvalid(t *testing.T) {
 		"fake_session_key",
 	).Return(nil, errors.New("invalid session"))
 
-	handler := MakeDebugHandler(svc, testConfig, nil, nil, nil)
+	handler := MakeDebugHandler(svc, testConfig, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "https://fleetdm.com/debug/pprof/profile", nil)
 	req.Header.Add("Authorization", "BEARER fake_session_key")
@@ -79,7 +82,7 @@ func TestDebugHandlerAuthenticationSuccess(t *testing.T) {
 		uint(42),
 	).Return(&fleet.User{}, nil)
 
-	handler := MakeDebugHandler(svc, testConfig, nil, nil, nil)
+	handler := MakeDebugHandler(svc, testConfig, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "https://fleetdm.com/debug/pprof/cmdline", nil)
 	req.Header.Add("Authorization", "BEARER fake_session_key")
@@ -88,3 +91,32 @@ func TestDebugHandlerAuthenticationSuccess(t *testing.T) {
 	handler.ServeHTTP(res, req)
 	assert.Equal(t, http.StatusOK, res.Code)
 }
+
+func TestDebugHandlerSlowEndpoints(t *testing.T) {
+	svc := &mockService{}
+	svc.On(
+		"GetSessionByKey",
+		mock.Anything,
+		"fake_session_key",
+	).Return(&fleet.Session{UserID: 42, ID: 1}, nil)
+	svc.On(
+		"UserUnauthorized",
+		mock.Anything,
+		uint(42),
+	).Return(&fleet.User{}, nil)
+
+	for _, lb := range []*latencybudget.Middleware{
+		nil,
+		latencybudget.NewMiddleware(kitlog.NewNopLogger(), func(string) time.Duration { return time.Second }, nil),
+	} {
+		handler := MakeDebugHandler(svc, testConfig, nil, nil, nil, lb)
+
+		req := httptest.NewRequest(http.MethodGet, "https://fleetdm.com/debug/slow-endpoints", nil)
+		req.Header.Add("Authorization", "BEARER fake_session_key")
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `[]`, res.Body.String())
+	}
+}
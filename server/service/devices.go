@@ -17,6 +17,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	apple_mdm "github.com/fleetdm/fleet/v4/server/mdm/apple"
 	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/cryptoutil"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 )
 
@@ -110,6 +111,7 @@ type getDeviceHostResponse struct {
 	Host                      *HostDetailResponse      `json:"host"`
 	OrgLogoURL                string                   `json:"org_logo_url"`
 	OrgLogoURLLightBackground string                   `json:"org_logo_url_light_background"`
+	OrgInfo                   fleet.DesktopOrgInfo     `json:"org_info"`
 	Err                       error                    `json:"error,omitempty"`
 	License                   fleet.LicenseInfo        `json:"license"`
 	GlobalConfig              fleet.DeviceGlobalConfig `json:"global_config"`
@@ -152,6 +154,11 @@ func getDeviceHostEndpoint(ctx context.Context, request interface{}, svc fleet.S
 		return getDeviceHostResponse{Err: err}, nil
 	}
 
+	branding, err := svc.GetDeviceBranding(ctx)
+	if err != nil {
+		return getDeviceHostResponse{Err: err}, nil
+	}
+
 	deviceGlobalConfig := fleet.DeviceGlobalConfig{
 		MDM: fleet.DeviceGlobalMDMConfig{
 			EnabledAndConfigured: ac.MDM.EnabledAndConfigured,
@@ -168,13 +175,96 @@ func getDeviceHostEndpoint(ctx context.Context, request interface{}, svc fleet.S
 	}
 
 	return getDeviceHostResponse{
-		Host:         resp,
-		OrgLogoURL:   ac.OrgInfo.OrgLogoURL,
-		License:      *license,
-		GlobalConfig: deviceGlobalConfig,
+		Host:                      resp,
+		OrgLogoURL:                branding.OrgLogoURL,
+		OrgLogoURLLightBackground: branding.OrgLogoURLLightBackground,
+		OrgInfo:                   branding,
+		License:                   *license,
+		GlobalConfig:              deviceGlobalConfig,
 	}, nil
 }
 
+// GetDeviceBranding returns the branding of the end-user facing pages for the
+// currently authenticated device, i.e. the organization's branding with the
+// overrides of the device's team.
+func (svc *Service) GetDeviceBranding(ctx context.Context) (fleet.DesktopOrgInfo, error) {
+	// must be device-authenticated, no additional authorization is required
+	if !svc.authz.IsAuthenticatedWith(ctx, authz.AuthnDeviceToken) {
+		return fleet.DesktopOrgInfo{}, ctxerr.Wrap(ctx, fleet.NewPermissionError("forbidden: only device-authenticated hosts can access this endpoint"))
+	}
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.DesktopOrgInfo{}, ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+	}
+
+	branding, err := svc.hostBranding(ctx, host)
+	if err != nil {
+		return fleet.DesktopOrgInfo{}, err
+	}
+	return fleet.DesktopOrgInfo{
+		OrgName:                   branding.OrgName,
+		OrgLogoURL:                branding.OrgLogoURL,
+		OrgLogoURLLightBackground: branding.OrgLogoURLLightBackground,
+		ContactURL:                branding.ContactURL,
+		PrimaryColor:              branding.PrimaryColor,
+		BackgroundColor:           branding.BackgroundColor,
+	}, nil
+}
+
+// hostBranding returns the organization's branding with the overrides of the
+// tenant and the team of the host.
+func (svc *Service) hostBranding(ctx context.Context, host *fleet.Host) (fleet.OrgInfo, error) {
+	var team *fleet.Team
+	if host.TeamID != nil {
+		var err error
+		team, err = svc.ds.Team(ctx, *host.TeamID)
+		if err != nil {
+			return fleet.OrgInfo{}, ctxerr.Wrap(ctx, err, "get host team")
+		}
+	}
+	var appConfig *fleet.AppConfig
+	var err error
+	if team != nil && team.TenantID != nil {
+		appConfig, err = svc.ds.TenantAppConfig(ctx, *team.TenantID)
+	} else {
+		appConfig, err = svc.ds.AppConfig(ctx)
+	}
+	if err != nil {
+		return fleet.OrgInfo{}, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	return fleet.ResolveBranding(appConfig, team), nil
+}
+
+// hostMDMPushCertTopic returns the APNs topic the host enrolls with, which is
+// the topic of the APNs certificate of the tenant of the host's team if the
+// tenant has one.
+func (svc *Service) hostMDMPushCertTopic(ctx context.Context, host *fleet.Host) (string, error) {
+	if host.TeamID == nil {
+		return svc.mdmPushCertTopic, nil
+	}
+	team, err := svc.ds.Team(ctx, *host.TeamID)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get host team")
+	}
+	if team.TenantID == nil {
+		return svc.mdmPushCertTopic, nil
+	}
+	assets, err := svc.ds.GetTenantMDMAssets(ctx, *team.TenantID, []fleet.TenantMDMAssetName{fleet.TenantMDMAssetAPNSCert})
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get tenant APNs certificate")
+	}
+	certPEM, ok := assets[fleet.TenantMDMAssetAPNSCert]
+	if !ok {
+		return svc.mdmPushCertTopic, nil
+	}
+	topic, err := cryptoutil.TopicFromPEMCert(certPEM)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get tenant APNs certificate topic")
+	}
+	return topic, nil
+}
+
 func (svc *Service) GetHostDEPAssignment(ctx context.Context, host *fleet.Host) (*fleet.HostDEPAssignment, error) {
 	alreadyAuthd := svc.authz.IsAuthenticatedWith(ctx, authz.AuthnDeviceToken)
 	if !alreadyAuthd {
@@ -207,6 +297,13 @@ func (svc *Service) AuthenticateDevice(ctx context.Context, authToken string) (*
 		return nil, false, ctxerr.Wrap(ctx, err, "authenticate device")
 	}
 
+	// device-authenticated requests come from Fleet Desktop (or the My device
+	// page it opens), so the host is online even if osquery did not check in.
+	// As for osquery, the seen times are batched.
+	if err := svc.task.RecordHostSeenByChannel(ctx, host.ID, fleet.HostSeenChannelFleetDesktop); err != nil {
+		logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "record host seen by fleet desktop"))
+	}
+
 	return host, svc.debugEnabledForHost(ctx, host.ID), nil
 }
 
@@ -496,16 +593,33 @@ func (svc *Service) GetDeviceMDMAppleEnrollmentProfile(ctx context.Context) ([]b
 		return nil, ctxerr.Wrap(ctx, fleet.NewPermissionError("forbidden: only device-authenticated hosts can access this endpoint"))
 	}
 
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+	}
+
 	appConfig, err := svc.ds.AppConfig(ctx)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err)
 	}
 
+	// the organization name of the profile uses the branding of the host's team
+	branding, err := svc.hostBranding(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// the hosts of a tenant with its own APNs certificate enroll with its topic
+	topic, err := svc.hostMDMPushCertTopic(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
 	enrollmentProf, err := apple_mdm.GenerateEnrollmentProfileMobileconfig(
-		appConfig.OrgInfo.OrgName,
+		branding.OrgName,
 		appConfig.ServerSettings.ServerURL,
 		svc.config.MDM.AppleSCEPChallenge,
-		svc.mdmPushCertTopic,
+		topic,
 	)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "generating manual enrollment profile")
@@ -544,6 +658,13 @@ func rotateEncryptionKeyEndpoint(ctx context.Context, request interface{}, svc f
 		return rotateEncryptionKeyResponse{Err: err}, nil
 	}
 
+	// Linux hosts escrow their LUKS key without MDM
+	if host.FleetPlatform() != "linux" {
+		if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+			return rotateEncryptionKeyResponse{Err: err}, nil
+		}
+	}
+
 	if err := svc.RequestEncryptionKeyRotation(ctx, host.ID); err != nil {
 		return rotateEncryptionKeyResponse{Err: err}, nil
 	}
@@ -422,4 +422,161 @@ func TestGetFleetDesktopSummary(t *testing.T) {
 		}
 
 	})
+	t.Run("localized messages", func(t *testing.T) {
+		ds := new(mock.Store)
+		license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+		svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+
+		ds.FailingPoliciesCountFunc = func(ctx context.Context, host *fleet.Host) (uint, error) {
+			return 0, nil
+		}
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{Localization: fleet.LocalizationSettings{Locale: "fr"}}, nil
+		}
+		ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+			return &fleet.Team{ID: tid, Config: fleet.TeamConfig{Localization: fleet.LocalizationSettings{Locale: "pt-BR"}}}, nil
+		}
+		ds.ListTranslationsFunc = func(ctx context.Context) ([]*fleet.Translations, error) {
+			return []*fleet.Translations{
+				{Locale: "fr", Messages: map[fleet.MessageKey]string{fleet.MessageDesktopMyDevice: "Mon appareil"}},
+				{Locale: "pt", Messages: map[fleet.MessageKey]string{fleet.MessageDesktopMyDevice: "Meu dispositivo"}},
+			}, nil
+		}
+
+		// hosts with no team use the global locale
+		sum, err := svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 1}))
+		require.NoError(t, err)
+		require.Equal(t, "fr", sum.Config.Locale)
+		require.Equal(t, "Mon appareil", sum.Config.Messages.Get(fleet.MessageDesktopMyDevice))
+		require.Equal(t, "Transparency", sum.Config.Messages.Get(fleet.MessageDesktopTransparency))
+
+		// the team locale overrides the global one and falls back to the base
+		// language
+		sum, err = svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 2, TeamID: ptr.Uint(1)}))
+		require.NoError(t, err)
+		require.Equal(t, "pt-BR", sum.Config.Locale)
+		require.Equal(t, "Meu dispositivo", sum.Config.Messages.Get(fleet.MessageDesktopMyDevice))
+
+		// no translations are loaded for the default locale
+		ds.ListTranslationsFuncInvoked = false
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{}, nil
+		}
+		sum, err = svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 1}))
+		require.NoError(t, err)
+		require.Equal(t, fleet.DefaultLocale, sum.Config.Locale)
+		require.Nil(t, sum.Config.Messages)
+		require.False(t, ds.ListTranslationsFuncInvoked)
+	})
+	t.Run("branding", func(t *testing.T) {
+		ds := new(mock.Store)
+		license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+		svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+
+		ds.FailingPoliciesCountFunc = func(ctx context.Context, host *fleet.Host) (uint, error) {
+			return 0, nil
+		}
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{OrgInfo: fleet.OrgInfo{
+				OrgName:      "Acme",
+				OrgLogoURL:   "https://acme.example/logo.png",
+				ContactURL:   "https://acme.example/help",
+				PrimaryColor: "#112233",
+			}}, nil
+		}
+		ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+			return &fleet.Team{ID: tid, Config: fleet.TeamConfig{Branding: fleet.OrgInfo{
+				OrgName:    "Acme EMEA",
+				ContactURL: "https://emea.acme.example/help",
+			}}}, nil
+		}
+
+		sum, err := svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 1}))
+		require.NoError(t, err)
+		require.Equal(t, fleet.DesktopOrgInfo{
+			OrgName:      "Acme",
+			OrgLogoURL:   "https://acme.example/logo.png",
+			ContactURL:   "https://acme.example/help",
+			PrimaryColor: "#112233",
+		}, sum.Config.OrgInfo)
+
+		// the team's branding overrides the organization's
+		sum, err = svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 2, TeamID: ptr.Uint(1)}))
+		require.NoError(t, err)
+		require.Equal(t, fleet.DesktopOrgInfo{
+			OrgName:      "Acme EMEA",
+			OrgLogoURL:   "https://acme.example/logo.png",
+			ContactURL:   "https://emea.acme.example/help",
+			PrimaryColor: "#112233",
+		}, sum.Config.OrgInfo)
+
+		branding, err := svc.GetDeviceBranding(test.HostContext(ctx, &fleet.Host{ID: 2, TeamID: ptr.Uint(1)}))
+		require.NoError(t, err)
+		require.Equal(t, sum.Config.OrgInfo, branding)
+
+		// the hosts of the teams of a tenant use the tenant's organization
+		ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+			return &fleet.Team{ID: tid, TenantID: ptr.Uint(1), Config: fleet.TeamConfig{Branding: fleet.OrgInfo{
+				ContactURL: "https://emea.globex.example/help",
+			}}}, nil
+		}
+		ds.TenantAppConfigFunc = func(ctx context.Context, tenantID uint) (*fleet.AppConfig, error) {
+			require.Equal(t, uint(1), tenantID)
+			return &fleet.AppConfig{OrgInfo: fleet.OrgInfo{
+				OrgName:    "Globex",
+				OrgLogoURL: "https://globex.example/logo.png",
+			}}, nil
+		}
+		sum, err = svc.GetFleetDesktopSummary(test.HostContext(ctx, &fleet.Host{ID: 3, TeamID: ptr.Uint(2)}))
+		require.NoError(t, err)
+		require.Equal(t, fleet.DesktopOrgInfo{
+			OrgName:    "Globex",
+			OrgLogoURL: "https://globex.example/logo.png",
+			ContactURL: "https://emea.globex.example/help",
+		}, sum.Config.OrgInfo)
+	})
+}
+
+func TestHostMDMPushCertTopic(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{APNSTopic: "com.apple.mgmt.Global"})
+
+	tenantCertPEM, _, err := generateCertWithTopic("com.apple.mgmt.Tenant")
+	require.NoError(t, err)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		switch tid {
+		case 1:
+			return &fleet.Team{ID: tid}, nil
+		case 2:
+			return &fleet.Team{ID: tid, TenantID: ptr.Uint(1)}, nil
+		default:
+			return &fleet.Team{ID: tid, TenantID: ptr.Uint(2)}, nil
+		}
+	}
+	ds.GetTenantMDMAssetsFunc = func(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error) {
+		require.Equal(t, []fleet.TenantMDMAssetName{fleet.TenantMDMAssetAPNSCert}, names)
+		if tenantID == 1 {
+			return map[fleet.TenantMDMAssetName][]byte{fleet.TenantMDMAssetAPNSCert: tenantCertPEM}, nil
+		}
+		return map[fleet.TenantMDMAssetName][]byte{}, nil
+	}
+
+	cases := []struct {
+		desc   string
+		teamID *uint
+		want   string
+	}{
+		{"no team", nil, "com.apple.mgmt.Global"},
+		{"team with no tenant", ptr.Uint(1), "com.apple.mgmt.Global"},
+		{"tenant with an APNs certificate", ptr.Uint(2), "com.apple.mgmt.Tenant"},
+		{"tenant without APNs certificate", ptr.Uint(3), "com.apple.mgmt.Global"},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			topic, err := svc.(validationMiddleware).Service.(*Service).hostMDMPushCertTopic(ctx, &fleet.Host{TeamID: c.teamID})
+			require.NoError(t, err)
+			require.Equal(t, c.want, topic)
+		})
+	}
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List duplicate hosts
+////////////////////////////////////////////////////////////////////////////////
+
+type listDuplicateHostsResponse struct {
+	DuplicateHosts []*fleet.DuplicateHostSet `json:"duplicate_hosts"`
+	Err            error                     `json:"error,omitempty"`
+}
+
+func (r listDuplicateHostsResponse) error() error { return r.Err }
+
+func listDuplicateHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	sets, err := svc.ListDuplicateHosts(ctx)
+	if err != nil {
+		return listDuplicateHostsResponse{Err: err}, nil
+	}
+	return listDuplicateHostsResponse{DuplicateHosts: sets}, nil
+}
+
+func (svc *Service) ListDuplicateHosts(ctx context.Context) ([]*fleet.DuplicateHostSet, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	sets, err := svc.ds.ListDuplicateHosts(ctx, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list duplicate hosts")
+	}
+	if sets == nil {
+		sets = []*fleet.DuplicateHostSet{}
+	}
+	return sets, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Merge duplicate hosts
+////////////////////////////////////////////////////////////////////////////////
+
+type mergeHostsRequest struct {
+	HostID  uint   `url:"id"`
+	HostIDs []uint `json:"host_ids"`
+}
+
+type mergeHostsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r mergeHostsResponse) error() error { return r.Err }
+func (r mergeHostsResponse) Status() int  { return http.StatusNoContent }
+
+func mergeHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*mergeHostsRequest)
+	if err := svc.MergeHosts(ctx, req.HostID, req.HostIDs); err != nil {
+		return mergeHostsResponse{Err: err}, nil
+	}
+	return mergeHostsResponse{}, nil
+}
+
+func (svc *Service) MergeHosts(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	target, err := svc.ds.HostLite(ctx, targetHostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get target host")
+	}
+	// authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, target, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if len(duplicateHostIDs) == 0 {
+		return fleet.NewInvalidArgumentError("host_ids", "at least one host must be provided")
+	}
+
+	seen := make(map[uint]bool, len(duplicateHostIDs))
+	ids := make([]uint, 0, len(duplicateHostIDs))
+	for _, id := range duplicateHostIDs {
+		if id == targetHostID {
+			return fleet.NewInvalidArgumentError("host_ids", "a host cannot be merged into itself")
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		dup, err := svc.ds.HostLite(ctx, id)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get duplicate host")
+		}
+		if err := svc.authz.Authorize(ctx, dup, fleet.ActionWrite); err != nil {
+			return err
+		}
+		if !fleet.IsDuplicateHost(target, dup) {
+			return fleet.NewInvalidArgumentError("host_ids", "host is not a duplicate of the target host: "+dup.DisplayName())
+		}
+		ids = append(ids, id)
+	}
+
+	return mergeHosts(ctx, svc.ds, authz.UserFromContext(ctx), target, ids)
+}
+
+func mergeHosts(ctx context.Context, ds fleet.Datastore, user *fleet.User, target *fleet.Host, duplicateHostIDs []uint) error {
+	if err := ds.MergeHosts(ctx, target.ID, duplicateHostIDs); err != nil {
+		return ctxerr.Wrap(ctx, err, "merge hosts")
+	}
+
+	if err := ds.NewActivity(ctx, user, fleet.ActivityTypeMergedDuplicateHosts{
+		HostID:          target.ID,
+		HostDisplayName: target.DisplayName(),
+		MergedHostIDs:   duplicateHostIDs,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for merged duplicate hosts")
+	}
+	return nil
+}
+
+// MergeDuplicateHosts merges each set of duplicate hosts into its most
+// recently seen host, if the automatic merge is enabled.
+func MergeDuplicateHosts(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if !appConfig.DuplicateHosts.AutoMerge {
+		return nil
+	}
+
+	filter := fleet.TeamFilter{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}}
+	sets, err := ds.ListDuplicateHosts(ctx, filter)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list duplicate hosts")
+	}
+
+	for _, set := range sets {
+		target, err := ds.HostLite(ctx, set.Hosts[0].ID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get target host")
+		}
+		ids := make([]uint, 0, len(set.Hosts)-1)
+		for _, h := range set.Hosts[1:] {
+			ids = append(ids, h.ID)
+		}
+		if err := mergeHosts(ctx, ds, nil, target, ids); err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "merged duplicate hosts", "host_id", target.ID, "merged_host_ids", fmt.Sprint(ids))
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateHostsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		teamHostID      = 1
+		teamDupHostID   = 2
+		globalHostID    = 3
+		globalDupHostID = 4
+	)
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		switch id {
+		case teamHostID, teamDupHostID:
+			return &fleet.Host{ID: id, TeamID: ptr.Uint(1), HardwareSerial: "serial1"}, nil
+		default:
+			return &fleet.Host{ID: id, HardwareSerial: "serial2"}, nil
+		}
+	}
+	ds.ListDuplicateHostsFunc = func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHostSet, error) {
+		return nil, nil
+	}
+	ds.MergeHostsFunc = func(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailList        bool
+		shouldFailTeamWrite   bool
+		shouldFailGlobalWrite bool
+	}{
+		{
+			name:                  "global admin",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+		},
+		{
+			name:                  "global maintainer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+		},
+		{
+			name:                  "global observer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team observer, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, DOES NOT belong to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListDuplicateHosts(ctx)
+			checkAuthErr(t, tt.shouldFailList, err)
+			err = svc.MergeHosts(ctx, teamHostID, []uint{teamDupHostID})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			err = svc.MergeHosts(ctx, globalHostID, []uint{globalDupHostID})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+		})
+	}
+}
+
+func TestMergeHosts(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, Hostname: "target", HardwareSerial: "S1", UUID: "U1"},
+		2: {ID: 2, HardwareSerial: "S1", UUID: "U2"},
+		3: {ID: 3, HardwareSerial: "S3", UUID: "U1"},
+		4: {ID: 4, HardwareSerial: "S4", UUID: "U4"},
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		h, ok := hosts[id]
+		if !ok {
+			return nil, newNotFoundError()
+		}
+		return h, nil
+	}
+	var mergedIDs []uint
+	ds.MergeHostsFunc = func(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
+		require.EqualValues(t, 1, targetHostID)
+		mergedIDs = duplicateHostIDs
+		return nil
+	}
+	var activity *fleet.ActivityTypeMergedDuplicateHosts
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		a := act.(fleet.ActivityTypeMergedDuplicateHosts)
+		activity = &a
+		return nil
+	}
+
+	var argErr *fleet.InvalidArgumentError
+	err := svc.MergeHosts(ctx, 1, nil)
+	require.ErrorAs(t, err, &argErr)
+	err = svc.MergeHosts(ctx, 1, []uint{2, 1})
+	require.ErrorAs(t, err, &argErr)
+	err = svc.MergeHosts(ctx, 1, []uint{2, 4})
+	require.ErrorAs(t, err, &argErr)
+	require.False(t, ds.MergeHostsFuncInvoked)
+
+	err = svc.MergeHosts(ctx, 1, []uint{99})
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+
+	err = svc.MergeHosts(ctx, 1, []uint{2, 3, 2})
+	require.NoError(t, err)
+	require.Equal(t, []uint{2, 3}, mergedIDs)
+	require.NotNil(t, activity)
+	require.EqualValues(t, 1, activity.HostID)
+	require.Equal(t, "target", activity.HostDisplayName)
+	require.Equal(t, []uint{2, 3}, activity.MergedHostIDs)
+}
+
+func TestMergeDuplicateHostsCron(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	appCfg := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appCfg, nil
+	}
+	ds.ListDuplicateHostsFunc = func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHostSet, error) {
+		return []*fleet.DuplicateHostSet{
+			{Hosts: []*fleet.DuplicateHost{{ID: 3}, {ID: 1}, {ID: 2}}},
+			{Hosts: []*fleet.DuplicateHost{{ID: 5}, {ID: 4}}},
+		}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id}, nil
+	}
+	merged := make(map[uint][]uint)
+	ds.MergeHostsFunc = func(ctx context.Context, targetHostID uint, duplicateHostIDs []uint) error {
+		merged[targetHostID] = duplicateHostIDs
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		return nil
+	}
+
+	// disabled by default
+	err := MergeDuplicateHosts(ctx, ds, kitlog.NewNopLogger())
+	require.NoError(t, err)
+	require.False(t, ds.ListDuplicateHostsFuncInvoked)
+
+	appCfg.DuplicateHosts.AutoMerge = true
+	err = MergeDuplicateHosts(ctx, ds, kitlog.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, map[uint][]uint{3: {1, 2}, 5: {4}}, merged)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"html/template"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List email templates
+////////////////////////////////////////////////////////////////////////////////
+
+type listEmailTemplatesRequest struct{}
+
+type listEmailTemplatesResponse struct {
+	EmailTemplates []*fleet.EmailTemplate `json:"email_templates"`
+	Err            error                  `json:"error,omitempty"`
+}
+
+func (r listEmailTemplatesResponse) error() error { return r.Err }
+
+func listEmailTemplatesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	tmpls, err := svc.ListEmailTemplates(ctx)
+	if err != nil {
+		return listEmailTemplatesResponse{Err: err}, nil
+	}
+	return listEmailTemplatesResponse{EmailTemplates: tmpls}, nil
+}
+
+func (svc *Service) ListEmailTemplates(ctx context.Context) ([]*fleet.EmailTemplate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	custom, err := svc.ds.ListEmailTemplates(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list email templates")
+	}
+	byName := make(map[fleet.EmailTemplateName]*fleet.EmailTemplate, len(custom))
+	for _, tmpl := range custom {
+		byName[tmpl.Name] = tmpl
+	}
+
+	tmpls := make([]*fleet.EmailTemplate, 0, len(fleet.EmailTemplateNames))
+	for _, name := range fleet.EmailTemplateNames {
+		tmpl := byName[name]
+		if tmpl == nil {
+			tmpl = &fleet.EmailTemplate{Name: name}
+		}
+		if err := withEmailTemplateDefaults(tmpl); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "email template defaults")
+		}
+		tmpls = append(tmpls, tmpl)
+	}
+	return tmpls, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get email template
+////////////////////////////////////////////////////////////////////////////////
+
+type getEmailTemplateRequest struct {
+	Name fleet.EmailTemplateName `url:"name"`
+}
+
+type getEmailTemplateResponse struct {
+	EmailTemplate *fleet.EmailTemplate `json:"email_template,omitempty"`
+	Err           error                `json:"error,omitempty"`
+}
+
+func (r getEmailTemplateResponse) error() error { return r.Err }
+
+func getEmailTemplateEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getEmailTemplateRequest)
+	tmpl, err := svc.GetEmailTemplate(ctx, req.Name)
+	if err != nil {
+		return getEmailTemplateResponse{Err: err}, nil
+	}
+	return getEmailTemplateResponse{EmailTemplate: tmpl}, nil
+}
+
+func (svc *Service) GetEmailTemplate(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := svc.customEmailTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := withEmailTemplateDefaults(tmpl); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "email template defaults")
+	}
+	return tmpl, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify email template
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyEmailTemplateRequest struct {
+	Name fleet.EmailTemplateName `url:"name"`
+	fleet.EmailTemplatePayload
+}
+
+type modifyEmailTemplateResponse struct {
+	EmailTemplate *fleet.EmailTemplate `json:"email_template,omitempty"`
+	Err           error                `json:"error,omitempty"`
+}
+
+func (r modifyEmailTemplateResponse) error() error { return r.Err }
+
+func modifyEmailTemplateEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyEmailTemplateRequest)
+	tmpl, err := svc.ModifyEmailTemplate(ctx, req.Name, req.EmailTemplatePayload)
+	if err != nil {
+		return modifyEmailTemplateResponse{Err: err}, nil
+	}
+	return modifyEmailTemplateResponse{EmailTemplate: tmpl}, nil
+}
+
+func (svc *Service) ModifyEmailTemplate(ctx context.Context, name fleet.EmailTemplateName, payload fleet.EmailTemplatePayload) (*fleet.EmailTemplate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	current, err := svc.customEmailTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := payload.Apply(*current)
+	if err := validateEmailTemplate(tmpl); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate email template")
+	}
+
+	saved, err := svc.ds.SaveEmailTemplate(ctx, &tmpl)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save email template")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedEmailTemplate{
+		Name: name,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for email template modification")
+	}
+
+	if err := withEmailTemplateDefaults(saved); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "email template defaults")
+	}
+	return saved, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Reset email template
+////////////////////////////////////////////////////////////////////////////////
+
+type resetEmailTemplateRequest struct {
+	Name fleet.EmailTemplateName `url:"name"`
+}
+
+type resetEmailTemplateResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r resetEmailTemplateResponse) error() error { return r.Err }
+
+func resetEmailTemplateEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*resetEmailTemplateRequest)
+	if err := svc.ResetEmailTemplate(ctx, req.Name); err != nil {
+		return resetEmailTemplateResponse{Err: err}, nil
+	}
+	return resetEmailTemplateResponse{}, nil
+}
+
+func (svc *Service) ResetEmailTemplate(ctx context.Context, name fleet.EmailTemplateName) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if !name.IsValid() {
+		return ctxerr.Wrap(ctx, newNotFoundError(), "unknown email template")
+	}
+
+	if err := svc.ds.DeleteEmailTemplate(ctx, name); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete email template")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeResetEmailTemplate{
+		Name: name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for email template reset")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Preview email template
+////////////////////////////////////////////////////////////////////////////////
+
+type previewEmailTemplateRequest struct {
+	Name fleet.EmailTemplateName `url:"name"`
+	fleet.EmailTemplatePayload
+}
+
+type previewEmailTemplateResponse struct {
+	*fleet.EmailTemplatePreview
+	Err error `json:"error,omitempty"`
+}
+
+func (r previewEmailTemplateResponse) error() error { return r.Err }
+
+func previewEmailTemplateEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*previewEmailTemplateRequest)
+	preview, err := svc.PreviewEmailTemplate(ctx, req.Name, req.EmailTemplatePayload)
+	if err != nil {
+		return previewEmailTemplateResponse{Err: err}, nil
+	}
+	return previewEmailTemplateResponse{EmailTemplatePreview: preview}, nil
+}
+
+func (svc *Service) PreviewEmailTemplate(ctx context.Context, name fleet.EmailTemplateName, payload fleet.EmailTemplatePayload) (*fleet.EmailTemplatePreview, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the payload's changes are previewed on top of the current
+	// customization, without saving them.
+	current, err := svc.customEmailTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := payload.Apply(*current)
+	if err := validateEmailTemplate(tmpl); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate email template")
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	var userName string
+	if user := authz.UserFromContext(ctx); user != nil {
+		userName = user.Name
+		if userName == "" {
+			userName = user.Email
+		}
+	}
+	mailer, err := mail.SampleMailer(name, tmpl.Body, mail.SampleData{
+		BaseURL:    template.URL(appConfig.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+		AssetURL:   getAssetURL(),
+		OrgName:    appConfig.OrgInfo.OrgName,
+		ContactURL: appConfig.OrgInfo.ContactURL,
+		UserName:   userName,
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "sample mailer")
+	}
+	html, err := mailer.Message()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "render email template")
+	}
+
+	var e fleet.Email
+	if appConfig.SMTPSettings != nil {
+		e.SMTPSettings = *appConfig.SMTPSettings
+	}
+	tmpl.ApplyTo(&e)
+	from := e.FromAddress()
+	if e.SenderName != "" {
+		from = e.SenderName + " <" + from + ">"
+	}
+	return &fleet.EmailTemplatePreview{
+		Subject: e.Subject,
+		From:    from,
+		HTML:    string(html),
+	}, nil
+}
+
+// customEmailTemplate returns the customization of the email template name,
+// with empty fields if it is not customized.
+func (svc *Service) customEmailTemplate(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+	if !name.IsValid() {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "unknown email template")
+	}
+	tmpl, err := svc.ds.EmailTemplate(ctx, name)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return &fleet.EmailTemplate{Name: name}, nil
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get email template")
+	}
+	return tmpl, nil
+}
+
+// emailWithTemplate returns the email with the customizations of the email
+// template name applied, along with the customized HTML template to use for
+// its body (empty to use the default template).
+func (svc *Service) emailWithTemplate(ctx context.Context, name fleet.EmailTemplateName, e fleet.Email) (fleet.Email, string, error) {
+	tmpl, err := svc.customEmailTemplate(ctx, name)
+	if err != nil {
+		return e, "", err
+	}
+	tmpl.ApplyTo(&e)
+	return e, tmpl.Body, nil
+}
+
+// withEmailTemplateDefaults fills the empty subject and body of the email
+// template with the defaults, so that they can be used as a starting point to
+// customize the email.
+func withEmailTemplateDefaults(tmpl *fleet.EmailTemplate) error {
+	if tmpl.Subject == "" {
+		tmpl.Subject = tmpl.Name.DefaultSubject()
+	}
+	if tmpl.Body == "" {
+		body, err := mail.DefaultTemplate(tmpl.Name)
+		if err != nil {
+			return err
+		}
+		tmpl.Body = body
+	}
+	tmpl.Variables = mail.TemplateVariables(tmpl.Name)
+	return nil
+}
+
+func validateEmailTemplate(tmpl fleet.EmailTemplate) error {
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+	if tmpl.Body != "" {
+		if err := mail.ValidateTemplate(tmpl.Name, tmpl.Body); err != nil {
+			return fleet.NewInvalidArgumentError("body", err.Error())
+		}
+	}
+	return nil
+}
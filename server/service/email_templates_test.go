@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailTemplatesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	// all the templates are customized so that the compiled-in defaults are
+	// not loaded.
+	customTemplate := func(name fleet.EmailTemplateName) *fleet.EmailTemplate {
+		return &fleet.EmailTemplate{Name: name, Subject: "Subject", Body: "<p>{{.Token}}</p>", Custom: true}
+	}
+	ds.ListEmailTemplatesFunc = func(ctx context.Context) ([]*fleet.EmailTemplate, error) {
+		var tmpls []*fleet.EmailTemplate
+		for _, name := range fleet.EmailTemplateNames {
+			tmpls = append(tmpls, customTemplate(name))
+		}
+		return tmpls, nil
+	}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		return customTemplate(name), nil
+	}
+	ds.SaveEmailTemplateFunc = func(ctx context.Context, tmpl *fleet.EmailTemplate) (*fleet.EmailTemplate, error) {
+		return tmpl, nil
+	}
+	ds.DeleteEmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) error {
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, true},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListEmailTemplates(ctx)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.GetEmailTemplate(ctx, fleet.EmailTemplateInvite)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.ModifyEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{Subject: ptr.String("Join us")})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.PreviewEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			err = svc.ResetEmailTemplate(ctx, fleet.EmailTemplateInvite)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+}
+
+func TestModifyEmailTemplate(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{Name: "Bob", GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	saved := map[fleet.EmailTemplateName]*fleet.EmailTemplate{}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		if tmpl, ok := saved[name]; ok {
+			cp := *tmpl
+			return &cp, nil
+		}
+		return nil, newTestNotFoundError()
+	}
+	ds.SaveEmailTemplateFunc = func(ctx context.Context, tmpl *fleet.EmailTemplate) (*fleet.EmailTemplate, error) {
+		cp := *tmpl
+		cp.Custom = true
+		saved[tmpl.Name] = &cp
+		return &cp, nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			OrgInfo:        fleet.OrgInfo{OrgName: "Acme"},
+			ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.acme.com"},
+			SMTPSettings:   &fleet.SMTPSettings{SMTPSenderAddress: "fleet@acme.com"},
+		}, nil
+	}
+
+	// unknown template
+	_, err := svc.ModifyEmailTemplate(ctx, "foo", fleet.EmailTemplatePayload{})
+	require.True(t, fleet.IsNotFound(err))
+
+	// invalid payloads
+	cases := []struct {
+		payload fleet.EmailTemplatePayload
+		wantErr string
+	}{
+		{fleet.EmailTemplatePayload{Subject: ptr.String("Hello\r\nBcc: x@example.com")}, "subject"},
+		{fleet.EmailTemplatePayload{SenderAddress: ptr.String("not an email")}, "sender_address"},
+		{fleet.EmailTemplatePayload{Body: ptr.String("{{.Token")}, "invalid template"},
+		{fleet.EmailTemplatePayload{Body: ptr.String("{{.Token}} {{.Password}}")}, "unknown variable {{.Password}}"},
+		{fleet.EmailTemplatePayload{Body: ptr.String("<p>Welcome!</p>")}, "must use the {{.Token}} variable"},
+	}
+	for _, c := range cases {
+		_, err := svc.ModifyEmailTemplate(ctx, fleet.EmailTemplateInvite, c.payload)
+		var invalid *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &invalid)
+		require.ErrorContains(t, err, c.wantErr)
+	}
+	require.Empty(t, saved)
+	require.Empty(t, activities)
+
+	body := `<p>{{.InvitedBy}} invited {{.Name}} to {{.OrgName}}: {{.BaseURL}}/login/invites/{{.Token}}</p>`
+	tmpl, err := svc.ModifyEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{
+		Subject:    ptr.String(" Join Acme "),
+		Body:       &body,
+		SenderName: ptr.String("Acme IT"),
+	})
+	require.NoError(t, err)
+	require.True(t, tmpl.Custom)
+	require.Equal(t, "Join Acme", tmpl.Subject)
+	require.Equal(t, body, tmpl.Body)
+	require.Equal(t, "Acme IT", tmpl.SenderName)
+	require.Contains(t, tmpl.Variables, "Token")
+	require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeEditedEmailTemplate{Name: fleet.EmailTemplateInvite}}, activities)
+
+	// a partial update keeps the other customizations
+	tmpl, err = svc.ModifyEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{
+		SenderAddress: ptr.String("it@acme.com"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Join Acme", tmpl.Subject)
+	require.Equal(t, body, tmpl.Body)
+	require.Equal(t, "it@acme.com", tmpl.SenderAddress)
+
+	// preview the saved customization
+	preview, err := svc.PreviewEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{})
+	require.NoError(t, err)
+	require.Equal(t, "Join Acme", preview.Subject)
+	require.Equal(t, "Acme IT <it@acme.com>", preview.From)
+	require.Equal(t, "<p>Bob invited Jane Doe to Acme: https://fleet.acme.com/login/invites/c2FtcGxlLXRva2Vu</p>", preview.HTML)
+
+	// preview unsaved changes
+	preview, err = svc.PreviewEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{
+		Subject:       ptr.String(""),
+		SenderName:    ptr.String(""),
+		SenderAddress: ptr.String(""),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "You are Invited to Fleet", preview.Subject)
+	require.Equal(t, "fleet@acme.com", preview.From)
+	require.Equal(t, "it@acme.com", saved[fleet.EmailTemplateInvite].SenderAddress)
+
+	_, err = svc.PreviewEmailTemplate(ctx, fleet.EmailTemplateInvite, fleet.EmailTemplatePayload{Body: ptr.String("{{.Nope}}")})
+	require.ErrorContains(t, err, "unknown variable {{.Nope}}")
+}
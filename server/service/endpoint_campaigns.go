@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
@@ -28,16 +29,20 @@ func makeStreamDistributedQueryCampaignResultsHandler(config config.ServerConfig
 	opt.Websocket = true
 	opt.RawWebsocket = true
 
+	// sockjs uses gorilla websockets under-the-hood see https://github.com/igm/sockjs-go/blob/master/v3/sockjs/rawwebsocket.go#L12-L14
+	// permessage-deflate compression is only used if the client supports it.
+	upgrader := &gws.Upgrader{
+		EnableCompression: config.WebsocketsCompression,
+	}
 	if config.WebsocketsAllowUnsafeOrigin {
 		opt.CheckOrigin = func(r *http.Request) bool {
 			return true
 		}
-		// sockjs uses gorilla websockets under-the-hood see https://github.com/igm/sockjs-go/blob/master/v3/sockjs/rawwebsocket.go#L12-L14
-		opt.WebsocketUpgrader = &gws.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			}}
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return true
+		}
 	}
+	opt.WebsocketUpgrader = upgrader
 
 	return func(path string) http.Handler {
 		// expand the path's versions (with regex) to all literal paths (no regex),
@@ -97,6 +102,7 @@ func makeStreamDistributedQueryCampaignResultsHandler(config config.ServerConfig
 
 			var info struct {
 				CampaignID uint `json:"campaign_id"`
+				fleet.LiveQueryStreamOptions
 			}
 			err = json.Unmarshal(*(msg.Data.(*json.RawMessage)), &info)
 			if err != nil {
@@ -110,7 +116,13 @@ func makeStreamDistributedQueryCampaignResultsHandler(config config.ServerConfig
 				return
 			}
 
-			svc.StreamCampaignResults(ctx, conn, info.CampaignID)
+			if info.Version < 0 || info.Version > fleet.LiveQueryStreamVersion2 {
+				logger.Log("err", "unsupported version", "version", info.Version)
+				conn.WriteJSONError(fmt.Sprintf("unsupported version %d", info.Version)) //nolint:errcheck
+				return
+			}
+
+			svc.StreamCampaignResults(ctx, conn, info.CampaignID, info.LiveQueryStreamOptions)
 		}
 
 		// multiplex the requests to each literal path that this endpoint support,
@@ -184,6 +184,9 @@ func authenticatedUser(svc fleet.Service, next endpoint.Endpoint) endpoint.Endpo
 			if v.User.IsAdminForcedPasswordReset() {
 				return nil, fleet.ErrPasswordResetRequired
 			}
+			if err := checkMFAEnrollment(ctx, svc, v.User); err != nil {
+				return nil, err
+			}
 
 			return next(ctx, request)
 		}
@@ -202,6 +205,9 @@ func authenticatedUser(svc fleet.Service, next endpoint.Endpoint) endpoint.Endpo
 		if v.User.IsAdminForcedPasswordReset() {
 			return nil, fleet.ErrPasswordResetRequired
 		}
+		if err := checkMFAEnrollment(ctx, svc, v.User); err != nil {
+			return nil, err
+		}
 
 		ctx = viewer.NewContext(ctx, *v)
 		if ac, ok := authz_ctx.FromContext(ctx); ok {
@@ -213,6 +219,26 @@ func authenticatedUser(svc fleet.Service, next endpoint.Endpoint) endpoint.Endpo
 	return logged(authUserFunc)
 }
 
+// checkMFAEnrollment returns fleet.ErrMFAEnrollmentRequired if the
+// organization requires MFA and the user did not enroll a second factor yet,
+// unless the request is one of those needed to do that enrollment.
+func checkMFAEnrollment(ctx context.Context, svc fleet.Service, user *fleet.User) error {
+	method, _ := ctx.Value(kithttp.ContextKeyRequestMethod).(string)
+	path, _ := ctx.Value(kithttp.ContextKeyRequestPath).(string)
+	if fleet.MFAEnrollmentAllowsRequest(method, path) {
+		return nil
+	}
+
+	required, err := svc.MFAEnrollmentRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fleet.ErrMFAEnrollmentRequired
+	}
+	return nil
+}
+
 func unauthenticatedRequest(svc fleet.Service, next endpoint.Endpoint) endpoint.Endpoint {
 	return logged(next)
 }
@@ -236,9 +262,19 @@ func logged(next endpoint.Endpoint) endpoint.Endpoint {
 }
 
 // authViewer creates an authenticated viewer by validating the session key.
+// If the key is not a session key, it is validated as a team-scoped API
+// token.
 func authViewer(ctx context.Context, sessionKey string, svc fleet.Service) (*viewer.Viewer, error) {
 	session, err := svc.GetSessionByKey(ctx, sessionKey)
 	if err != nil {
+		var nfe fleet.NotFoundError
+		if errors.As(err, &nfe) {
+			if v, tokErr := apiTokenViewer(ctx, sessionKey, svc); tokErr == nil {
+				return v, nil
+			} else if !errors.As(tokErr, &nfe) {
+				return nil, tokErr
+			}
+		}
 		return nil, fleet.NewAuthRequiredError(err.Error())
 	}
 	user, err := svc.UserUnauthorized(ctx, session.UserID)
@@ -247,3 +283,19 @@ func authViewer(ctx context.Context, sessionKey string, svc fleet.Service) (*vie
 	}
 	return &viewer.Viewer{User: user, Session: session}, nil
 }
+
+// apiTokenViewer creates an authenticated viewer by validating a team-scoped
+// API token, and verifies that the token's scope allows the current request.
+func apiTokenViewer(ctx context.Context, apiToken string, svc fleet.Service) (*viewer.Viewer, error) {
+	tok, user, err := svc.AuthenticateAPIToken(ctx, apiToken)
+	if err != nil {
+		return nil, err
+	}
+
+	method, _ := ctx.Value(kithttp.ContextKeyRequestMethod).(string)
+	path, _ := ctx.Value(kithttp.ContextKeyRequestPath).(string)
+	if !tok.Scope.AllowsRequest(method, path) {
+		return nil, fleet.NewPermissionError(fmt.Sprintf("API token scope %q does not allow %s %s", tok.Scope, method, path))
+	}
+	return &viewer.Viewer{User: user, Session: &fleet.Session{UserID: user.ID, Key: apiToken, APIOnly: &user.APIOnly}}, nil
+}
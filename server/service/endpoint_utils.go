@@ -363,6 +363,7 @@ type authEndpointer struct {
 	alternativePaths  []string
 	customMiddleware  []endpoint.Middleware
 	usePathPrefix     bool
+	gitOpsMode        bool
 }
 
 func newDeviceAuthenticatedEndpointer(svc fleet.Service, logger log.Logger, opts []kithttp.ServerOption, r *mux.Router, versions ...string) *authEndpointer {
@@ -582,9 +583,12 @@ func (e *authEndpointer) handleEndpoint(path string, f handlerFunc, v interface{
 }
 
 func (e *authEndpointer) makeEndpoint(f handlerFunc, v interface{}) http.Handler {
-	next := func(ctx context.Context, request interface{}) (interface{}, error) {
+	var next endpoint.Endpoint = func(ctx context.Context, request interface{}) (interface{}, error) {
 		return f(ctx, request, e.svc)
 	}
+	if e.gitOpsMode {
+		next = verifyGitOpsModeAllowed(e.svc, next)
+	}
 	endp := e.authFunc(e.svc, next)
 
 	// apply middleware in reverse order so that the first wraps the second
@@ -621,6 +625,15 @@ func (e *authEndpointer) WithCustomMiddleware(mws ...endpoint.Middleware) *authE
 	return &ae
 }
 
+// WithGitOpsMode returns an endpointer for the endpoints that modify the
+// configuration, which are only available to the users allowed to apply
+// GitOps when GitOps mode is enabled.
+func (e *authEndpointer) WithGitOpsMode() *authEndpointer {
+	ae := *e
+	ae.gitOpsMode = true
+	return &ae
+}
+
 func (e *authEndpointer) UsePathPrefix() *authEndpointer {
 	ae := *e
 	ae.usePathPrefix = true
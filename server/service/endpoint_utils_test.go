@@ -272,6 +272,9 @@ func TestEndpointer(t *testing.T) {
 	ds.MarkSessionAccessedFunc = func(ctx context.Context, session *fleet.Session) error {
 		return nil
 	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
 	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
 		return &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, nil
 	}
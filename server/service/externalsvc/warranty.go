@@ -0,0 +1,348 @@
+package externalsvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+)
+
+// Supported warranty vendors, they match the fleet.WarrantyVendor values.
+const (
+	WarrantyVendorApple  = "apple"
+	WarrantyVendorDell   = "dell"
+	WarrantyVendorLenovo = "lenovo"
+)
+
+// Default URLs of the vendors' warranty APIs.
+const (
+	defaultAppleGSXURL = "https://partner-connect.apple.com"
+	defaultDellURL     = "https://apigtwb2c.us.dell.com"
+	defaultLenovoURL   = "https://supportapi.lenovo.com"
+)
+
+// ErrWarrantyNotFound is returned by LookupWarranty when the vendor does not
+// know the serial number or has no warranty information for it.
+var ErrWarrantyNotFound = errors.New("warranty not found")
+
+// Warranty is the warranty information of a device as returned by a vendor's
+// API. Any of the dates may be nil if the vendor did not report it.
+type Warranty struct {
+	PurchaseDate *time.Time
+	EndDate      *time.Time
+}
+
+// WarrantyOptions defines the options to configure a warranty client.
+type WarrantyOptions struct {
+	Vendor string
+	// URL overrides the default URL of the vendor's API.
+	URL      string
+	ClientID string
+	APIKey   string
+}
+
+// WarrantyClient is a client to look up the warranty of devices from a
+// vendor's API.
+type WarrantyClient struct {
+	opts    WarrantyOptions
+	baseURL string
+	client  *http.Client
+
+	// the OAuth access token, only used for Dell.
+	mu             sync.Mutex
+	token          string
+	tokenExpiresAt time.Time
+}
+
+// NewWarrantyClient returns a client to use to look up the warranty of
+// devices from the vendor's API.
+func NewWarrantyClient(opts *WarrantyOptions) (*WarrantyClient, error) {
+	baseURL := opts.URL
+	if baseURL == "" {
+		switch opts.Vendor {
+		case WarrantyVendorApple:
+			baseURL = defaultAppleGSXURL
+		case WarrantyVendorDell:
+			baseURL = defaultDellURL
+		case WarrantyVendorLenovo:
+			baseURL = defaultLenovoURL
+		default:
+			return nil, fmt.Errorf("unsupported warranty vendor %q", opts.Vendor)
+		}
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("parse warranty API URL: %w", err)
+	}
+
+	return &WarrantyClient{
+		opts:    *opts,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  fleethttp.NewClient(fleethttp.WithTimeout(30 * time.Second)),
+	}, nil
+}
+
+// LookupWarranty returns the warranty information of the device with the
+// provided serial number. It returns ErrWarrantyNotFound if the vendor has no
+// information for that serial number.
+func (c *WarrantyClient) LookupWarranty(ctx context.Context, serial string) (*Warranty, error) {
+	switch c.opts.Vendor {
+	case WarrantyVendorApple:
+		return c.lookupApple(ctx, serial)
+	case WarrantyVendorDell:
+		return c.lookupDell(ctx, serial)
+	case WarrantyVendorLenovo:
+		return c.lookupLenovo(ctx, serial)
+	default:
+		return nil, fmt.Errorf("unsupported warranty vendor %q", c.opts.Vendor)
+	}
+}
+
+func (c *WarrantyClient) lookupApple(ctx context.Context, serial string) (*Warranty, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"device": map[string]string{"id": serial},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal GSX request: %w", err)
+	}
+
+	var resp struct {
+		Device struct {
+			WarrantyInfo struct {
+				PurchaseDate    string `json:"purchaseDate"`
+				CoverageEndDate string `json:"coverageEndDate"`
+			} `json:"warrantyInfo"`
+		} `json:"device"`
+	}
+	err = c.doWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/gsx/api/repair/product/details", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Apple-SoldTo", c.opts.ClientID)
+		req.Header.Set("X-Apple-ShipTo", c.opts.ClientID)
+		req.Header.Set("X-Apple-Auth-Token", c.opts.APIKey)
+		return req, nil
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWarranty(resp.Device.WarrantyInfo.PurchaseDate, resp.Device.WarrantyInfo.CoverageEndDate)
+}
+
+func (c *WarrantyClient) lookupDell(ctx context.Context, serial string) (*Warranty, error) {
+	token, err := c.dellToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []struct {
+		ServiceTag   string `json:"serviceTag"`
+		Invalid      bool   `json:"invalid"`
+		ShipDate     string `json:"shipDate"`
+		Entitlements []struct {
+			EndDate string `json:"endDate"`
+		} `json:"entitlements"`
+	}
+	err = c.doWithRetry(ctx, func() (*http.Request, error) {
+		u := c.baseURL + "/PROD/sbil/eapi/v5/asset-entitlements?servicetags=" + url.QueryEscape(serial)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req, nil
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 || resp[0].Invalid {
+		return nil, ErrWarrantyNotFound
+	}
+
+	// the warranty ends with the last entitlement
+	var endDate string
+	for _, ent := range resp[0].Entitlements {
+		if ent.EndDate > endDate {
+			endDate = ent.EndDate
+		}
+	}
+	return newWarranty(resp[0].ShipDate, endDate)
+}
+
+// dellToken returns a valid OAuth access token, requesting a new one if
+// needed.
+func (c *WarrantyClient) dellToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.tokenExpiresAt) {
+		return c.token, nil
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	err := c.doWithRetry(ctx, func() (*http.Request, error) {
+		form := url.Values{
+			"client_id":     {c.opts.ClientID},
+			"client_secret": {c.opts.APIKey},
+			"grant_type":    {"client_credentials"},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/auth/oauth/v2/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, &resp)
+	if err != nil {
+		if errors.Is(err, ErrWarrantyNotFound) {
+			// not a warranty lookup, do not report it as a missing warranty
+			err = errors.New("token endpoint not found")
+		}
+		return "", fmt.Errorf("get Dell access token: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("get Dell access token: empty token")
+	}
+
+	c.token = resp.AccessToken
+	// renew the token a bit before it expires
+	c.tokenExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *WarrantyClient) lookupLenovo(ctx context.Context, serial string) (*Warranty, error) {
+	var resp struct {
+		ErrorCode    int    `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+		Purchased    string `json:"Purchased"`
+		Warranty     []struct {
+			End string `json:"End"`
+		} `json:"Warranty"`
+	}
+	err := c.doWithRetry(ctx, func() (*http.Request, error) {
+		u := c.baseURL + "/v2.5/warranty?Serial=" + url.QueryEscape(serial)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("ClientID", c.opts.APIKey)
+		return req, nil
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrorCode != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrWarrantyNotFound, resp.ErrorMessage)
+	}
+
+	var endDate string
+	for _, w := range resp.Warranty {
+		if w.End > endDate {
+			endDate = w.End
+		}
+	}
+	return newWarranty(resp.Purchased, endDate)
+}
+
+// doWithRetry sends the request created by newReq and decodes the JSON
+// response in dst. Rate-limited requests and server errors are retried.
+func (c *WarrantyClient) doWithRetry(ctx context.Context, newReq func() (*http.Request, error), dst interface{}) error {
+	op := func() error {
+		req, err := newReq()
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// retryable error
+				return err
+			}
+			return backoff.Permanent(err)
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return backoff.Permanent(ErrWarrantyNotFound)
+
+		case resp.StatusCode == http.StatusTooManyRequests:
+			afterSecs, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 0)
+			if err == nil && (time.Duration(afterSecs)*time.Second) < maxWaitForRetryAfter {
+				// the retry-after duration is reasonable, wait for it and return a
+				// retryable error so that we try again.
+				time.Sleep(time.Duration(afterSecs) * time.Second)
+				return errors.New("retry after requested delay")
+			}
+			return backoff.Permanent(fmt.Errorf("%s warranty API rate limit exceeded", c.opts.Vendor))
+
+		case resp.StatusCode >= http.StatusInternalServerError:
+			// 500+ status, can be worth retrying
+			return fmt.Errorf("%s warranty API returned status %d", c.opts.Vendor, resp.StatusCode)
+
+		case resp.StatusCode >= http.StatusBadRequest:
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return backoff.Permanent(fmt.Errorf("%s warranty API returned status %d: %s", c.opts.Vendor, resp.StatusCode, string(b)))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			return backoff.Permanent(fmt.Errorf("decode %s warranty API response: %w", c.opts.Vendor, err))
+		}
+		return nil
+	}
+
+	boff := backoff.WithMaxRetries(backoff.NewConstantBackOff(retryBackoff), uint64(maxRetries))
+	return backoff.Retry(op, backoff.WithContext(boff, ctx))
+}
+
+// newWarranty returns the warranty with the provided dates, or
+// ErrWarrantyNotFound if both are empty.
+func newWarranty(purchaseDate, endDate string) (*Warranty, error) {
+	if purchaseDate == "" && endDate == "" {
+		return nil, ErrWarrantyNotFound
+	}
+
+	var w Warranty
+	var err error
+	if w.PurchaseDate, err = parseWarrantyDate(purchaseDate); err != nil {
+		return nil, err
+	}
+	if w.EndDate, err = parseWarrantyDate(endDate); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// parseWarrantyDate parses the date returned by a vendor's API, which may or
+// may not include the time. Only the date is kept.
+func parseWarrantyDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid warranty date %q", s)
+}
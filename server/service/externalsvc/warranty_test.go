@@ -0,0 +1,165 @@
+package externalsvc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarrantyApple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/gsx/api/repair/product/details", r.URL.Path)
+		require.Equal(t, "soldto", r.Header.Get("X-Apple-SoldTo"))
+		require.Equal(t, "token", r.Header.Get("X-Apple-Auth-Token"))
+
+		var body struct {
+			Device struct {
+				ID string `json:"id"`
+			} `json:"device"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.Device.ID {
+		case "C02OK":
+			_, _ = w.Write([]byte(`{"device": {"warrantyInfo": {"purchaseDate": "2022-03-01T00:00:00Z", "coverageEndDate": "2025-03-01T00:00:00Z"}}}`))
+		case "C02EMPTY":
+			_, _ = w.Write([]byte(`{"device": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewWarrantyClient(&WarrantyOptions{Vendor: WarrantyVendorApple, URL: srv.URL, ClientID: "soldto", APIKey: "token"})
+	require.NoError(t, err)
+
+	w, err := client.LookupWarranty(context.Background(), "C02OK")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), *w.PurchaseDate)
+	require.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *w.EndDate)
+
+	_, err = client.LookupWarranty(context.Background(), "C02EMPTY")
+	require.ErrorIs(t, err, ErrWarrantyNotFound)
+	_, err = client.LookupWarranty(context.Background(), "C02NOPE")
+	require.ErrorIs(t, err, ErrWarrantyNotFound)
+}
+
+func TestWarrantyDell(t *testing.T) {
+	var tokenCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/oauth/v2/token":
+			tokenCalls++
+			require.NoError(t, r.ParseForm())
+			if r.PostForm.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			require.Equal(t, "id", r.PostForm.Get("client_id"))
+			_, _ = w.Write([]byte(`{"access_token": "abc", "expires_in": 3600}`))
+
+		case "/PROD/sbil/eapi/v5/asset-entitlements":
+			require.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+			switch r.URL.Query().Get("servicetags") {
+			case "DELL1":
+				_, _ = w.Write([]byte(`[{"serviceTag": "DELL1", "invalid": false, "shipDate": "2021-06-15T05:00:00Z",
+					"entitlements": [{"endDate": "2024-06-15T04:59:59.999Z"}, {"endDate": "2026-06-15T04:59:59.999Z"}]}]`))
+			case "INVALID":
+				_, _ = w.Write([]byte(`[{"serviceTag": "INVALID", "invalid": true}]`))
+			default:
+				_, _ = w.Write([]byte(`[]`))
+			}
+
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewWarrantyClient(&WarrantyOptions{Vendor: WarrantyVendorDell, URL: srv.URL, ClientID: "id", APIKey: "secret"})
+	require.NoError(t, err)
+
+	w, err := client.LookupWarranty(context.Background(), "DELL1")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC), *w.PurchaseDate)
+	require.Equal(t, time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC), *w.EndDate)
+
+	_, err = client.LookupWarranty(context.Background(), "INVALID")
+	require.ErrorIs(t, err, ErrWarrantyNotFound)
+	_, err = client.LookupWarranty(context.Background(), "NOPE")
+	require.ErrorIs(t, err, ErrWarrantyNotFound)
+
+	// the access token is reused
+	require.Equal(t, 1, tokenCalls)
+
+	client, err = NewWarrantyClient(&WarrantyOptions{Vendor: WarrantyVendorDell, URL: srv.URL, ClientID: "id", APIKey: "wrong"})
+	require.NoError(t, err)
+	_, err = client.LookupWarranty(context.Background(), "DELL1")
+	require.ErrorContains(t, err, "get Dell access token")
+	require.NotErrorIs(t, err, ErrWarrantyNotFound)
+}
+
+func TestWarrantyLenovo(t *testing.T) {
+	var countCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		countCalls++
+		require.Equal(t, "/v2.5/warranty", r.URL.Path)
+		require.Equal(t, "token", r.Header.Get("ClientID"))
+		switch r.URL.Query().Get("Serial") {
+		case "LEN1":
+			_, _ = w.Write([]byte(`{"ErrorCode": 0, "Purchased": "2023-01-10T00:00:00Z",
+				"Warranty": [{"End": "2026-01-09T00:00:00Z"}, {"End": "2024-01-09T00:00:00Z"}]}`))
+		case "RETRY":
+			if countCalls == 1 {
+				w.Header().Add("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"ErrorCode": 0, "Purchased": "2023-01-10"}`))
+		case "FAIL":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = io.WriteString(w, `{"ErrorCode": 100, "ErrorMessage": "Invalid serial number"}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewWarrantyClient(&WarrantyOptions{Vendor: WarrantyVendorLenovo, URL: srv.URL, APIKey: "token"})
+	require.NoError(t, err)
+
+	w, err := client.LookupWarranty(context.Background(), "LEN1")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), *w.PurchaseDate)
+	require.Equal(t, time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), *w.EndDate)
+
+	_, err = client.LookupWarranty(context.Background(), "NOPE")
+	require.ErrorIs(t, err, ErrWarrantyNotFound)
+	require.ErrorContains(t, err, "Invalid serial number")
+
+	countCalls = 0
+	w, err = client.LookupWarranty(context.Background(), "RETRY")
+	require.NoError(t, err)
+	require.Equal(t, 2, countCalls)
+	require.Nil(t, w.EndDate)
+
+	countCalls = 0
+	_, err = client.LookupWarranty(context.Background(), "FAIL")
+	require.ErrorContains(t, err, "lenovo warranty API returned status 500")
+	require.NotErrorIs(t, err, ErrWarrantyNotFound)
+	require.Equal(t, maxRetries+1, countCalls)
+}
+
+func TestNewWarrantyClient(t *testing.T) {
+	_, err := NewWarrantyClient(&WarrantyOptions{Vendor: "hp"})
+	require.ErrorContains(t, err, `unsupported warranty vendor "hp"`)
+
+	client, err := NewWarrantyClient(&WarrantyOptions{Vendor: WarrantyVendorDell})
+	require.NoError(t, err)
+	require.Equal(t, defaultDellURL, client.baseURL)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func (svc *Service) VerifyGitOpsModeAllowed(ctx context.Context) error {
+	appCfg, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		// skipauth: Authorization is done by the endpoint if allowed.
+		svc.authz.SkipAuthorization(ctx)
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if !appCfg.GitOpsMode.GitOpsModeEnabled {
+		return nil
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if ok && vc.User.CanApplyInGitOpsMode() {
+		return nil
+	}
+	// skipauth: Authorization is done by the endpoint if allowed.
+	svc.authz.SkipAuthorization(ctx)
+	return ctxerr.Wrap(ctx, &fleet.GitOpsModeError{RepositoryURL: appCfg.GitOpsMode.RepositoryURL})
+}
+
+// verifyGitOpsModeAllowed returns an endpoint that fails with a
+// fleet.GitOpsModeError if GitOps mode is enabled and the authenticated user
+// is not allowed to change the configuration, and calls next otherwise. It
+// must be called after the user is authenticated.
+func verifyGitOpsModeAllowed(svc fleet.Service, next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if err := svc.VerifyGitOpsModeAllowed(ctx); err != nil {
+			return nil, err
+		}
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyGitOpsModeAllowed(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	var gitOpsMode fleet.GitOpsModeSettings
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{GitOpsMode: gitOpsMode}, nil
+	}
+
+	admin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
+	apiOnlyAdmin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin), APIOnly: true}
+	gitOps := &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}
+	teamGitOps := &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleGitOps}}}
+	teamAdmin := &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}
+
+	cases := []struct {
+		desc    string
+		enabled bool
+		user    *fleet.User
+		allowed bool
+	}{
+		{"disabled, admin", false, admin, true},
+		{"disabled, team admin", false, teamAdmin, true},
+		{"enabled, admin", true, admin, false},
+		{"enabled, team admin", true, teamAdmin, false},
+		{"enabled, api-only admin", true, apiOnlyAdmin, true},
+		{"enabled, gitops", true, gitOps, true},
+		{"enabled, team gitops", true, teamGitOps, true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			gitOpsMode = fleet.GitOpsModeSettings{GitOpsModeEnabled: c.enabled, RepositoryURL: "https://github.com/example/fleet-gitops"}
+			err := svc.VerifyGitOpsModeAllowed(viewer.NewContext(ctx, viewer.Viewer{User: c.user}))
+			if c.allowed {
+				require.NoError(t, err)
+				return
+			}
+			var gitOpsErr *fleet.GitOpsModeError
+			require.True(t, errors.As(err, &gitOpsErr))
+			require.ErrorContains(t, err, "https://github.com/example/fleet-gitops")
+		})
+	}
+}
+
+func TestModifyAppConfigGitOpsMode(t *testing.T) {
+	ds := new(mock.Store)
+
+	dsAppConfig := &fleet.AppConfig{
+		OrgInfo:        fleet.OrgInfo{OrgName: "Test"},
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://localhost:8080"},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return dsAppConfig.Copy(), nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		*dsAppConfig = *conf
+		return nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+
+	admin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
+	gitOps := &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}
+
+	t.Run("free license", func(t *testing.T) {
+		svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierFree}})
+		ctx = viewer.NewContext(ctx, viewer.Viewer{User: admin})
+		_, err := svc.ModifyAppConfig(ctx, []byte(`{"gitops": {"gitops_mode_enabled": true, "repository_url": "https://example.com/repo"}}`), fleet.ApplySpecOptions{})
+		require.ErrorContains(t, err, "gitops.gitops_mode_enabled")
+		require.False(t, dsAppConfig.GitOpsMode.GitOpsModeEnabled)
+	})
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	adminCtx := viewer.NewContext(ctx, viewer.Viewer{User: admin})
+	gitOpsCtx := viewer.NewContext(ctx, viewer.Viewer{User: gitOps})
+
+	// the repository URL is required
+	_, err := svc.ModifyAppConfig(adminCtx, []byte(`{"gitops": {"gitops_mode_enabled": true}}`), fleet.ApplySpecOptions{})
+	require.ErrorContains(t, err, "gitops.repository_url")
+
+	// enable GitOps mode
+	_, err = svc.ModifyAppConfig(adminCtx, []byte(`{"gitops": {"gitops_mode_enabled": true, "repository_url": "https://example.com/repo"}}`), fleet.ApplySpecOptions{})
+	require.NoError(t, err)
+	require.True(t, dsAppConfig.GitOpsMode.GitOpsModeEnabled)
+
+	// the admin cannot change the configuration anymore
+	_, err = svc.ModifyAppConfig(adminCtx, []byte(`{"org_info": {"org_name": "Changed"}}`), fleet.ApplySpecOptions{})
+	var gitOpsErr *fleet.GitOpsModeError
+	require.True(t, errors.As(err, &gitOpsErr))
+	require.Equal(t, "https://example.com/repo", gitOpsErr.RepositoryURL)
+	require.Equal(t, "Test", dsAppConfig.OrgInfo.OrgName)
+
+	// but GitOps can
+	_, err = svc.ModifyAppConfig(gitOpsCtx, []byte(`{"org_info": {"org_name": "Changed"}}`), fleet.ApplySpecOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "Changed", dsAppConfig.OrgInfo.OrgName)
+
+	// the admin can disable GitOps mode
+	_, err = svc.ModifyAppConfig(adminCtx, []byte(`{"gitops": {"gitops_mode_enabled": false}}`), fleet.ApplySpecOptions{})
+	require.NoError(t, err)
+	require.False(t, dsAppConfig.GitOpsMode.GitOpsModeEnabled)
+
+	// and change the configuration again
+	_, err = svc.ModifyAppConfig(adminCtx, []byte(`{"org_info": {"org_name": "Test"}}`), fleet.ApplySpecOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "Test", dsAppConfig.OrgInfo.OrgName)
+}
@@ -170,6 +170,9 @@ func TestRemoveGlobalPoliciesFromWebhookConfig(t *testing.T) {
 		storedAppConfig = *info
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	for _, tc := range []struct {
 		name     string
@@ -10,6 +10,7 @@ import (
 	"regexp"
 
 	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/clientcert"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/publicip"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -25,8 +26,10 @@ import (
 	scep_depot "github.com/fleetdm/fleet/v4/server/mdm/scep/depot"
 	scepserver "github.com/fleetdm/fleet/v4/server/mdm/scep/server"
 	"github.com/fleetdm/fleet/v4/server/service/middleware/authzcheck"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/latencybudget"
 	"github.com/fleetdm/fleet/v4/server/service/middleware/mdmconfigured"
 	"github.com/fleetdm/fleet/v4/server/service/middleware/ratelimit"
+	"github.com/fleetdm/fleet/v4/server/service/middleware/usageanalytics"
 	"github.com/go-kit/kit/endpoint"
 	kithttp "github.com/go-kit/kit/transport/http"
 	kitlog "github.com/go-kit/log"
@@ -85,6 +88,17 @@ func logRequestEnd(logger kitlog.Logger) func(context.Context, http.ResponseWrit
 	}
 }
 
+// tagLatencyBudgetExceeded tags the log of the requests that exceeded the
+// latency budget of their API path, they are logged even if debug logging is
+// disabled.
+func tagLatencyBudgetExceeded(ctx context.Context, w http.ResponseWriter) context.Context {
+	if budget, exceeded := latencybudget.Exceeded(ctx); exceeded {
+		logging.WithExtras(ctx, "latency_budget_exceeded", true, "latency_budget", budget)
+		logging.WithLevel(ctx, level.Info)
+	}
+	return ctx
+}
+
 func checkLicenseExpiration(svc fleet.Service) func(context.Context, http.ResponseWriter) context.Context {
 	return func(ctx context.Context, w http.ResponseWriter) context.Context {
 		license, err := svc.License(ctx)
@@ -100,6 +114,8 @@ func checkLicenseExpiration(svc fleet.Service) func(context.Context, http.Respon
 
 type extraHandlerOpts struct {
 	loginRateLimit *throttled.Rate
+	latencyBudget  *latencybudget.Middleware
+	usageAnalytics *usageanalytics.Middleware
 }
 
 // ExtraHandlerOption allows adding extra configuration to the HTTP handler.
@@ -112,6 +128,22 @@ func WithLoginRateLimit(r throttled.Rate) ExtraHandlerOption {
 	}
 }
 
+// WithLatencyBudget configures the middleware tracking the requests that
+// exceed their latency budget.
+func WithLatencyBudget(m *latencybudget.Middleware) ExtraHandlerOption {
+	return func(o *extraHandlerOpts) {
+		o.latencyBudget = m
+	}
+}
+
+// WithUsageAnalytics configures the middleware counting the API requests for
+// the detailed usage analytics.
+func WithUsageAnalytics(m *usageanalytics.Middleware) ExtraHandlerOption {
+	return func(o *extraHandlerOpts) {
+		o.usageAnalytics = m
+	}
+}
+
 // MakeHandler creates an HTTP handler for the Fleet server endpoints.
 func MakeHandler(
 	svc fleet.Service,
@@ -134,6 +166,7 @@ func MakeHandler(
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerAfter(
 			kithttp.SetContentType("application/json; charset=utf-8"),
+			tagLatencyBudgetExceeded,
 			logRequestEnd(logger),
 			checkLicenseExpiration(svc),
 		),
@@ -149,6 +182,13 @@ func MakeHandler(
 	}
 
 	r.Use(publicIP)
+	r.Use(clientCertificate)
+	if eopts.latencyBudget != nil {
+		r.Use(eopts.latencyBudget.Handler)
+	}
+	if eopts.usageAnalytics != nil {
+		r.Use(eopts.usageAnalytics.Handler)
+	}
 
 	attachFleetAPIRoutes(r, svc, config, logger, limitStore, fleetAPIOptions, eopts)
 	addMetrics(r)
@@ -166,6 +206,18 @@ func publicIP(handler http.Handler) http.Handler {
 	})
 }
 
+// clientCertificate adds the TLS client certificate of the request to its
+// context, if it was verified against the trusted client certificate
+// authorities (see the server.client_certificate_enrollment option).
+func clientCertificate(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			r = r.WithContext(clientcert.NewContext(r.Context(), r.TLS.VerifiedChains[0][0]))
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // PrometheusMetricsHandler wraps the provided handler with prometheus metrics
 // middleware and returns the resulting handler that should be mounted for that
 // route.
@@ -260,34 +312,68 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// user-authenticated endpoints
 	ue := newUserAuthenticatedEndpointer(svc, opts, r, apiVersions...)
+	// user-authenticated endpoints that modify the configuration, only
+	// available to GitOps when GitOps mode is enabled.
+	ueGitOps := ue.WithGitOpsMode()
 
 	ue.POST("/api/_version_/fleet/trigger", triggerEndpoint, triggerRequest{})
+	ue.GET("/api/_version_/fleet/usage_analytics", getUsageAnalyticsEndpoint, getUsageAnalyticsRequest{})
+
+	ue.GET("/api/_version_/fleet/crons", listCronSchedulesEndpoint, listCronSchedulesRequest{})
+	ue.POST("/api/_version_/fleet/crons/{name}/trigger", triggerCronScheduleEndpoint, triggerCronScheduleRequest{})
+	ue.POST("/api/_version_/fleet/crons/{name}/pause", pauseCronScheduleEndpoint, pauseCronScheduleRequest{})
+	ue.POST("/api/_version_/fleet/crons/{name}/resume", resumeCronScheduleEndpoint, pauseCronScheduleRequest{})
 
 	ue.GET("/api/_version_/fleet/me", meEndpoint, nil)
+	ue.GET("/api/_version_/fleet/me/mfa", getMFAStatusEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/totp", beginTOTPEnrollmentEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/totp/confirm", confirmTOTPEnrollmentEndpoint, confirmTOTPEnrollmentRequest{})
+	ue.DELETE("/api/_version_/fleet/me/mfa/totp", disableTOTPEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/webauthn/begin", beginWebAuthnRegistrationEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/webauthn/finish", finishWebAuthnRegistrationEndpoint, finishWebAuthnRegistrationRequest{})
+	ue.DELETE("/api/_version_/fleet/me/mfa/webauthn/{id:[0-9]+}", deleteWebAuthnCredentialEndpoint, deleteWebAuthnCredentialRequest{})
+	ue.POST("/api/_version_/fleet/me/mfa/recovery_codes", regenerateMFARecoveryCodesEndpoint, nil)
 	ue.GET("/api/_version_/fleet/sessions/{id:[0-9]+}", getInfoAboutSessionEndpoint, getInfoAboutSessionRequest{})
 	ue.DELETE("/api/_version_/fleet/sessions/{id:[0-9]+}", deleteSessionEndpoint, deleteSessionRequest{})
 
 	ue.GET("/api/_version_/fleet/config/certificate", getCertificateEndpoint, nil)
 	ue.GET("/api/_version_/fleet/config", getAppConfigEndpoint, nil)
 	ue.PATCH("/api/_version_/fleet/config", modifyAppConfigEndpoint, modifyAppConfigRequest{})
-	ue.POST("/api/_version_/fleet/spec/enroll_secret", applyEnrollSecretSpecEndpoint, applyEnrollSecretSpecRequest{})
+	ue.GET("/api/_version_/fleet/config/versions", listConfigVersionsEndpoint, listConfigVersionsRequest{})
+	ue.GET("/api/_version_/fleet/config/versions/diff", diffConfigVersionsEndpoint, diffConfigVersionsRequest{})
+	ue.GET("/api/_version_/fleet/config/versions/{id:[0-9]+}", getConfigVersionEndpoint, getConfigVersionRequest{})
+	ueGitOps.POST("/api/_version_/fleet/config/versions/{id:[0-9]+}/rollback", rollbackConfigVersionEndpoint, rollbackConfigVersionRequest{})
+
+	ue.GET("/api/_version_/fleet/email_templates", listEmailTemplatesEndpoint, listEmailTemplatesRequest{})
+	ue.GET("/api/_version_/fleet/email_templates/{name}", getEmailTemplateEndpoint, getEmailTemplateRequest{})
+	ue.PATCH("/api/_version_/fleet/email_templates/{name}", modifyEmailTemplateEndpoint, modifyEmailTemplateRequest{})
+	ue.DELETE("/api/_version_/fleet/email_templates/{name}", resetEmailTemplateEndpoint, resetEmailTemplateRequest{})
+	ue.POST("/api/_version_/fleet/email_templates/{name}/preview", previewEmailTemplateEndpoint, previewEmailTemplateRequest{})
+
+	ue.GET("/api/_version_/fleet/translations", listTranslationsEndpoint, listTranslationsRequest{})
+	ue.GET("/api/_version_/fleet/translations/{locale}", getTranslationsEndpoint, getTranslationsRequest{})
+	ue.PUT("/api/_version_/fleet/translations/{locale}", applyTranslationsEndpoint, applyTranslationsRequest{})
+	ue.DELETE("/api/_version_/fleet/translations/{locale}", deleteTranslationsEndpoint, deleteTranslationsRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/enroll_secret", applyEnrollSecretSpecEndpoint, applyEnrollSecretSpecRequest{})
 	ue.GET("/api/_version_/fleet/spec/enroll_secret", getEnrollSecretSpecEndpoint, nil)
 	ue.GET("/api/_version_/fleet/version", versionEndpoint, nil)
 
 	ue.POST("/api/_version_/fleet/users/roles/spec", applyUserRoleSpecsEndpoint, applyUserRoleSpecsRequest{})
 	ue.POST("/api/_version_/fleet/translate", translatorEndpoint, translatorRequest{})
-	ue.POST("/api/_version_/fleet/spec/teams", applyTeamSpecsEndpoint, applyTeamSpecsRequest{})
-	ue.PATCH("/api/_version_/fleet/teams/{team_id:[0-9]+}/secrets", modifyTeamEnrollSecretsEndpoint, modifyTeamEnrollSecretsRequest{})
-	ue.POST("/api/_version_/fleet/teams", createTeamEndpoint, createTeamRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/teams", applyTeamSpecsEndpoint, applyTeamSpecsRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/teams/{team_id:[0-9]+}/secrets", modifyTeamEnrollSecretsEndpoint, modifyTeamEnrollSecretsRequest{})
+	ueGitOps.POST("/api/_version_/fleet/teams", createTeamEndpoint, createTeamRequest{})
 	ue.GET("/api/_version_/fleet/teams", listTeamsEndpoint, listTeamsRequest{})
 	ue.GET("/api/_version_/fleet/teams/{id:[0-9]+}", getTeamEndpoint, getTeamRequest{})
-	ue.PATCH("/api/_version_/fleet/teams/{id:[0-9]+}", modifyTeamEndpoint, modifyTeamRequest{})
-	ue.DELETE("/api/_version_/fleet/teams/{id:[0-9]+}", deleteTeamEndpoint, deleteTeamRequest{})
-	ue.POST("/api/_version_/fleet/teams/{id:[0-9]+}/agent_options", modifyTeamAgentOptionsEndpoint, modifyTeamAgentOptionsRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/teams/{id:[0-9]+}", modifyTeamEndpoint, modifyTeamRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/teams/{id:[0-9]+}", deleteTeamEndpoint, deleteTeamRequest{})
+	ueGitOps.POST("/api/_version_/fleet/teams/{id:[0-9]+}/agent_options", modifyTeamAgentOptionsEndpoint, modifyTeamAgentOptionsRequest{})
 	ue.GET("/api/_version_/fleet/teams/{id:[0-9]+}/users", listTeamUsersEndpoint, listTeamUsersRequest{})
 	ue.PATCH("/api/_version_/fleet/teams/{id:[0-9]+}/users", addTeamUsersEndpoint, modifyTeamUsersRequest{})
 	ue.DELETE("/api/_version_/fleet/teams/{id:[0-9]+}/users", deleteTeamUsersEndpoint, modifyTeamUsersRequest{})
 	ue.GET("/api/_version_/fleet/teams/{id:[0-9]+}/secrets", teamEnrollSecretsEndpoint, teamEnrollSecretsRequest{})
+	ue.GET("/api/_version_/fleet/teams/{id:[0-9]+}/shared_library/opt_outs", getTeamSharedLibraryOptOutsEndpoint, getTeamSharedLibraryOptOutsRequest{})
+	ueGitOps.PUT("/api/_version_/fleet/teams/{id:[0-9]+}/shared_library/opt_outs", setTeamSharedLibraryOptOutsEndpoint, setTeamSharedLibraryOptOutsRequest{})
 
 	ue.GET("/api/_version_/fleet/users", listUsersEndpoint, listUsersRequest{})
 	ue.POST("/api/_version_/fleet/users/admin", createUserEndpoint, createUserRequest{})
@@ -297,8 +383,32 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/users/{id:[0-9]+}/require_password_reset", requirePasswordResetEndpoint, requirePasswordResetRequest{})
 	ue.GET("/api/_version_/fleet/users/{id:[0-9]+}/sessions", getInfoAboutSessionsForUserEndpoint, getInfoAboutSessionsForUserRequest{})
 	ue.DELETE("/api/_version_/fleet/users/{id:[0-9]+}/sessions", deleteSessionsForUserEndpoint, deleteSessionsForUserRequest{})
+	ue.DELETE("/api/_version_/fleet/users/{id:[0-9]+}/mfa", resetUserMFAEndpoint, resetUserMFARequest{})
 	ue.POST("/api/_version_/fleet/change_password", changePasswordEndpoint, changePasswordRequest{})
 
+	ue.POST("/api/_version_/fleet/api_tokens", createAPITokenEndpoint, createAPITokenRequest{})
+	ue.GET("/api/_version_/fleet/api_tokens", listAPITokensEndpoint, listAPITokensRequest{})
+	ue.DELETE("/api/_version_/fleet/api_tokens/{id:[0-9]+}", deleteAPITokenEndpoint, deleteAPITokenRequest{})
+
+	ue.POST("/api/_version_/fleet/tenants", createTenantEndpoint, createTenantRequest{})
+	ue.GET("/api/_version_/fleet/tenants", listTenantsEndpoint, nil)
+	ue.GET("/api/_version_/fleet/tenants/{id:[0-9]+}", getTenantEndpoint, getTenantRequest{})
+	ue.PATCH("/api/_version_/fleet/tenants/{id:[0-9]+}", modifyTenantEndpoint, modifyTenantRequest{})
+	ue.DELETE("/api/_version_/fleet/tenants/{id:[0-9]+}", deleteTenantEndpoint, deleteTenantRequest{})
+	ue.PATCH("/api/_version_/fleet/tenants/{id:[0-9]+}/teams", addTenantTeamsEndpoint, modifyTenantTeamsRequest{})
+	ue.DELETE("/api/_version_/fleet/tenants/{id:[0-9]+}/teams", deleteTenantTeamsEndpoint, modifyTenantTeamsRequest{})
+	ue.PATCH("/api/_version_/fleet/tenants/{id:[0-9]+}/users", addTenantUsersEndpoint, modifyTenantUsersRequest{})
+	ue.DELETE("/api/_version_/fleet/tenants/{id:[0-9]+}/users", deleteTenantUsersEndpoint, modifyTenantUsersRequest{})
+	ue.PUT("/api/_version_/fleet/tenants/{id:[0-9]+}/mdm/apple/apns", setTenantAPNsCertificateEndpoint, setTenantAPNsCertificateRequest{})
+
+	ueGitOps.POST("/api/_version_/fleet/secret_variables", createSecretVariableEndpoint, createSecretVariableRequest{})
+	ue.GET("/api/_version_/fleet/secret_variables", listSecretVariablesEndpoint, nil)
+	ueGitOps.DELETE("/api/_version_/fleet/secret_variables/{id:[0-9]+}", deleteSecretVariableEndpoint, deleteSecretVariableRequest{})
+	ueGitOps.PUT("/api/_version_/fleet/spec/secret_variables", applySecretVariablesSpecEndpoint, applySecretVariablesSpecRequest{})
+
+	ue.POST("/api/_version_/fleet/mdm/assets/export", exportMDMAssetsEndpoint, exportMDMAssetsRequest{})
+	ue.POST("/api/_version_/fleet/mdm/assets/import", importMDMAssetsEndpoint, importMDMAssetsRequest{})
+
 	ue.GET("/api/_version_/fleet/email/change/{token}", changeEmailEndpoint, changeEmailRequest{})
 	// TODO: searchTargetsEndpoint will be removed in Fleet 5.0
 	ue.POST("/api/_version_/fleet/targets", searchTargetsEndpoint, searchTargetsRequest{})
@@ -309,21 +419,23 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.DELETE("/api/_version_/fleet/invites/{id:[0-9]+}", deleteInviteEndpoint, deleteInviteRequest{})
 	ue.PATCH("/api/_version_/fleet/invites/{id:[0-9]+}", updateInviteEndpoint, updateInviteRequest{})
 
-	ue.EndingAtVersion("v1").POST("/api/_version_/fleet/global/policies", globalPolicyEndpoint, globalPolicyRequest{})
-	ue.StartingAtVersion("2022-04").POST("/api/_version_/fleet/policies", globalPolicyEndpoint, globalPolicyRequest{})
+	ueGitOps.EndingAtVersion("v1").POST("/api/_version_/fleet/global/policies", globalPolicyEndpoint, globalPolicyRequest{})
+	ueGitOps.StartingAtVersion("2022-04").POST("/api/_version_/fleet/policies", globalPolicyEndpoint, globalPolicyRequest{})
 	ue.EndingAtVersion("v1").GET("/api/_version_/fleet/global/policies", listGlobalPoliciesEndpoint, listGlobalPoliciesRequest{})
 	ue.StartingAtVersion("2022-04").GET("/api/_version_/fleet/policies", listGlobalPoliciesEndpoint, listGlobalPoliciesRequest{})
 	ue.GET("/api/_version_/fleet/policies/count", countGlobalPoliciesEndpoint, countGlobalPoliciesRequest{})
+	ue.GET("/api/_version_/fleet/policies/trends", getPolicyComplianceTrendsEndpoint, getPolicyComplianceTrendsRequest{})
 	ue.EndingAtVersion("v1").GET("/api/_version_/fleet/global/policies/{policy_id}", getPolicyByIDEndpoint, getPolicyByIDRequest{})
 	ue.StartingAtVersion("2022-04").GET("/api/_version_/fleet/policies/{policy_id}", getPolicyByIDEndpoint, getPolicyByIDRequest{})
-	ue.EndingAtVersion("v1").POST("/api/_version_/fleet/global/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
-	ue.StartingAtVersion("2022-04").POST("/api/_version_/fleet/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
-	ue.EndingAtVersion("v1").PATCH("/api/_version_/fleet/global/policies/{policy_id}", modifyGlobalPolicyEndpoint, modifyGlobalPolicyRequest{})
-	ue.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/policies/{policy_id}", modifyGlobalPolicyEndpoint, modifyGlobalPolicyRequest{})
+	ueGitOps.EndingAtVersion("v1").POST("/api/_version_/fleet/global/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
+	ueGitOps.StartingAtVersion("2022-04").POST("/api/_version_/fleet/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
+	ueGitOps.EndingAtVersion("v1").PATCH("/api/_version_/fleet/global/policies/{policy_id}", modifyGlobalPolicyEndpoint, modifyGlobalPolicyRequest{})
+	ueGitOps.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/policies/{policy_id}", modifyGlobalPolicyEndpoint, modifyGlobalPolicyRequest{})
+	ue.POST("/api/_version_/fleet/policies/{policy_id}/automations/dry_run", dryRunPolicyAutomationEndpoint, dryRunPolicyAutomationRequest{})
 	ue.POST("/api/_version_/fleet/automations/reset", resetAutomationEndpoint, resetAutomationRequest{})
 
 	// Alias /api/_version_/fleet/team/ -> /api/_version_/fleet/teams/
-	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies").
+	ueGitOps.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies").
 		POST("/api/_version_/fleet/teams/{team_id}/policies", teamPolicyEndpoint, teamPolicyRequest{})
 	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies").
 		GET("/api/_version_/fleet/teams/{team_id}/policies", listTeamPoliciesEndpoint, listTeamPoliciesRequest{})
@@ -331,30 +443,32 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 		GET("/api/_version_/fleet/teams/{team_id}/policies/count", countTeamPoliciesEndpoint, countTeamPoliciesRequest{})
 	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies/{policy_id}").
 		GET("/api/_version_/fleet/teams/{team_id}/policies/{policy_id}", getTeamPolicyByIDEndpoint, getTeamPolicyByIDRequest{})
-	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies/delete").
+	ueGitOps.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies/delete").
 		POST("/api/_version_/fleet/teams/{team_id}/policies/delete", deleteTeamPoliciesEndpoint, deleteTeamPoliciesRequest{})
-	ue.PATCH("/api/_version_/fleet/teams/{team_id}/policies/{policy_id}", modifyTeamPolicyEndpoint, modifyTeamPolicyRequest{})
-	ue.POST("/api/_version_/fleet/spec/policies", applyPolicySpecsEndpoint, applyPolicySpecsRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/teams/{team_id}/policies/{policy_id}", modifyTeamPolicyEndpoint, modifyTeamPolicyRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/policies", applyPolicySpecsEndpoint, applyPolicySpecsRequest{})
 
 	ue.GET("/api/_version_/fleet/queries/{id:[0-9]+}", getQueryEndpoint, getQueryRequest{})
 	ue.GET("/api/_version_/fleet/queries", listQueriesEndpoint, listQueriesRequest{})
 	ue.GET("/api/_version_/fleet/queries/{id:[0-9]+}/report", getQueryReportEndpoint, getQueryReportRequest{})
-	ue.POST("/api/_version_/fleet/queries", createQueryEndpoint, createQueryRequest{})
-	ue.PATCH("/api/_version_/fleet/queries/{id:[0-9]+}", modifyQueryEndpoint, modifyQueryRequest{})
-	ue.DELETE("/api/_version_/fleet/queries/{name}", deleteQueryEndpoint, deleteQueryRequest{})
-	ue.DELETE("/api/_version_/fleet/queries/id/{id:[0-9]+}", deleteQueryByIDEndpoint, deleteQueryByIDRequest{})
-	ue.POST("/api/_version_/fleet/queries/delete", deleteQueriesEndpoint, deleteQueriesRequest{})
-	ue.POST("/api/_version_/fleet/spec/queries", applyQuerySpecsEndpoint, applyQuerySpecsRequest{})
+	ueGitOps.POST("/api/_version_/fleet/queries", createQueryEndpoint, createQueryRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/queries/{id:[0-9]+}", modifyQueryEndpoint, modifyQueryRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/queries/{name}", deleteQueryEndpoint, deleteQueryRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/queries/id/{id:[0-9]+}", deleteQueryByIDEndpoint, deleteQueryByIDRequest{})
+	ueGitOps.POST("/api/_version_/fleet/queries/delete", deleteQueriesEndpoint, deleteQueriesRequest{})
+	ue.POST("/api/_version_/fleet/queries/validate", validateQueryEndpoint, validateQueryRequest{})
+	ue.GET("/api/_version_/fleet/schema/tables", listOsquerySchemaTablesEndpoint, listOsquerySchemaTablesRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/queries", applyQuerySpecsEndpoint, applyQuerySpecsRequest{})
 	ue.GET("/api/_version_/fleet/spec/queries", getQuerySpecsEndpoint, getQuerySpecsRequest{})
 	ue.GET("/api/_version_/fleet/spec/queries/{name}", getQuerySpecEndpoint, getQuerySpecRequest{})
 
 	ue.GET("/api/_version_/fleet/packs/{id:[0-9]+}", getPackEndpoint, getPackRequest{})
-	ue.POST("/api/_version_/fleet/packs", createPackEndpoint, createPackRequest{})
-	ue.PATCH("/api/_version_/fleet/packs/{id:[0-9]+}", modifyPackEndpoint, modifyPackRequest{})
+	ueGitOps.POST("/api/_version_/fleet/packs", createPackEndpoint, createPackRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/packs/{id:[0-9]+}", modifyPackEndpoint, modifyPackRequest{})
 	ue.GET("/api/_version_/fleet/packs", listPacksEndpoint, listPacksRequest{})
-	ue.DELETE("/api/_version_/fleet/packs/{name}", deletePackEndpoint, deletePackRequest{})
-	ue.DELETE("/api/_version_/fleet/packs/id/{id:[0-9]+}", deletePackByIDEndpoint, deletePackByIDRequest{})
-	ue.POST("/api/_version_/fleet/spec/packs", applyPackSpecsEndpoint, applyPackSpecsRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/packs/{name}", deletePackEndpoint, deletePackRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/packs/id/{id:[0-9]+}", deletePackByIDEndpoint, deletePackByIDRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/packs", applyPackSpecsEndpoint, applyPackSpecsRequest{})
 	ue.GET("/api/_version_/fleet/spec/packs", getPackSpecsEndpoint, nil)
 	ue.GET("/api/_version_/fleet/spec/packs/{name}", getPackSpecEndpoint, getGenericSpecRequest{})
 
@@ -370,11 +484,24 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	ue.GET("/api/_version_/fleet/software/titles", listSoftwareTitlesEndpoint, listSoftwareTitlesRequest{})
 	ue.GET("/api/_version_/fleet/software/titles/{id:[0-9]+}", getSoftwareTitleEndpoint, getSoftwareTitleRequest{})
+	ue.GET("/api/_version_/fleet/software/export", exportSoftwareEndpoint, exportSoftwareRequest{})
 
 	// Vulnerabilities
 	ue.GET("/api/_version_/fleet/vulnerabilities", listVulnerabilitiesEndpoint, listVulnerabilitiesRequest{})
+	// must be registered before the {cve} route, which would match it too
+	ue.GET("/api/_version_/fleet/vulnerabilities/export", exportVulnerabilitiesEndpoint, exportVulnerabilitiesRequest{})
+	ue.GET("/api/_version_/fleet/vulnerabilities/sla_breaches", getVulnerabilitySLAReportEndpoint, getVulnerabilitySLAReportRequest{})
 	ue.GET("/api/_version_/fleet/vulnerabilities/{cve}", getVulnerabilityEndpoint, getVulnerabilityRequest{})
 
+	// Admin notifications
+	ue.GET("/api/_version_/fleet/notifications", listAdminNotificationsEndpoint, listAdminNotificationsRequest{})
+	ue.POST("/api/_version_/fleet/notifications/read", markAdminNotificationsReadEndpoint, markAdminNotificationsReadRequest{})
+	ue.DELETE("/api/_version_/fleet/notifications/{id:[0-9]+}", deleteAdminNotificationEndpoint, deleteAdminNotificationRequest{})
+
+	// Data exports
+	ue.GET("/api/_version_/fleet/exports/{id:[0-9]+}", getDataExportEndpoint, getDataExportRequest{})
+	ue.GET("/api/_version_/fleet/exports/{id:[0-9]+}/download", downloadDataExportEndpoint, downloadDataExportRequest{})
+
 	// Hosts
 	ue.GET("/api/_version_/fleet/host_summary", getHostSummaryEndpoint, getHostSummaryRequest{})
 	ue.GET("/api/_version_/fleet/hosts", listHostsEndpoint, listHostsRequest{})
@@ -392,8 +519,13 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/device_mapping", listHostDeviceMappingEndpoint, listHostDeviceMappingRequest{})
 	ue.PUT("/api/_version_/fleet/hosts/{id:[0-9]+}/device_mapping", putHostDeviceMappingEndpoint, putHostDeviceMappingRequest{})
 	ue.GET("/api/_version_/fleet/hosts/report", hostsReportEndpoint, hostsReportRequest{})
+	ue.GET("/api/_version_/fleet/hosts/export", exportHostsEndpoint, exportHostsRequest{})
 	ue.GET("/api/_version_/fleet/os_versions", osVersionsEndpoint, osVersionsRequest{})
 	ue.GET("/api/_version_/fleet/os_versions/{id:[0-9]+}", getOSVersionEndpoint, getOSVersionRequest{})
+	ue.GET("/api/_version_/fleet/certificates", listCertificateInventoryEndpoint, listCertificateInventoryRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/certificates", listHostCertificatesEndpoint, listHostCertificatesRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/enrollment_certificates", listHostEnrollmentCertificatesEndpoint, listHostEnrollmentCertificatesRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/enrollment_certificates/revoke", revokeHostEnrollmentCertificatesEndpoint, revokeHostEnrollmentCertificatesRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/queries/{query_id:[0-9]+}", getHostQueryReportEndpoint, getHostQueryReportRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/health", getHostHealthEndpoint, getHostHealthRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/labels", addLabelsToHostEndpoint, addLabelsToHostRequest{})
@@ -402,18 +534,30 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/hosts/summary/mdm", getHostMDMSummary, getHostMDMSummaryRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/mdm", getHostMDM, getHostMDMRequest{})
 
-	ue.POST("/api/_version_/fleet/labels", createLabelEndpoint, createLabelRequest{})
-	ue.PATCH("/api/_version_/fleet/labels/{id:[0-9]+}", modifyLabelEndpoint, modifyLabelRequest{})
+	ueGitOps.POST("/api/_version_/fleet/labels", createLabelEndpoint, createLabelRequest{})
+	ueGitOps.PATCH("/api/_version_/fleet/labels/{id:[0-9]+}", modifyLabelEndpoint, modifyLabelRequest{})
 	ue.GET("/api/_version_/fleet/labels/{id:[0-9]+}", getLabelEndpoint, getLabelRequest{})
 	ue.GET("/api/_version_/fleet/labels", listLabelsEndpoint, listLabelsRequest{})
 	ue.GET("/api/_version_/fleet/labels/summary", getLabelsSummaryEndpoint, nil)
 	ue.GET("/api/_version_/fleet/labels/{id:[0-9]+}/hosts", listHostsInLabelEndpoint, listHostsInLabelRequest{})
-	ue.DELETE("/api/_version_/fleet/labels/{name}", deleteLabelEndpoint, deleteLabelRequest{})
-	ue.DELETE("/api/_version_/fleet/labels/id/{id:[0-9]+}", deleteLabelByIDEndpoint, deleteLabelByIDRequest{})
-	ue.POST("/api/_version_/fleet/spec/labels", applyLabelSpecsEndpoint, applyLabelSpecsRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/labels/{name}", deleteLabelEndpoint, deleteLabelRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/labels/id/{id:[0-9]+}", deleteLabelByIDEndpoint, deleteLabelByIDRequest{})
+	ueGitOps.POST("/api/_version_/fleet/spec/labels", applyLabelSpecsEndpoint, applyLabelSpecsRequest{})
 	ue.GET("/api/_version_/fleet/spec/labels", getLabelSpecsEndpoint, nil)
 	ue.GET("/api/_version_/fleet/spec/labels/{name}", getLabelSpecEndpoint, getGenericSpecRequest{})
 
+	ue.PATCH("/api/_version_/fleet/hosts/{id:[0-9]+}/custom_fields", updateHostCustomFieldsEndpoint, updateHostCustomFieldsRequest{})
+	ue.POST("/api/_version_/fleet/spec/host_custom_fields", applyHostCustomFieldsSpecEndpoint, applyHostCustomFieldsSpecRequest{})
+
+	ue.POST("/api/_version_/fleet/host_groups", createHostGroupEndpoint, createHostGroupRequest{})
+	ue.GET("/api/_version_/fleet/host_groups", listHostGroupsEndpoint, listHostGroupsRequest{})
+	ue.GET("/api/_version_/fleet/host_groups/{id:[0-9]+}", getHostGroupEndpoint, getHostGroupRequest{})
+	ue.PATCH("/api/_version_/fleet/host_groups/{id:[0-9]+}", modifyHostGroupEndpoint, modifyHostGroupRequest{})
+	ue.DELETE("/api/_version_/fleet/host_groups/{id:[0-9]+}", deleteHostGroupEndpoint, deleteHostGroupRequest{})
+	ue.POST("/api/_version_/fleet/host_groups/{id:[0-9]+}/hosts", addHostsToHostGroupEndpoint, hostGroupHostsRequest{})
+	ue.DELETE("/api/_version_/fleet/host_groups/{id:[0-9]+}/hosts", removeHostsFromHostGroupEndpoint, hostGroupHostsRequest{})
+	ue.POST("/api/_version_/fleet/host_groups/{id:[0-9]+}/scripts/run", runHostGroupScriptEndpoint, runHostGroupScriptRequest{})
+
 	// This endpoint runs live queries synchronously (with a configured timeout).
 	ue.POST("/api/_version_/fleet/queries/{id:[0-9]+}/run", runOneLiveQueryEndpoint, runOneLiveQueryRequest{})
 	// Old endpoint, removed from docs. This GET endpoint runs live queries synchronously (with a configured timeout).
@@ -425,27 +569,28 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/queries/run_by_names", createDistributedQueryCampaignByNamesEndpoint, createDistributedQueryCampaignByNamesRequest{})
 
 	ue.GET("/api/_version_/fleet/activities", listActivitiesEndpoint, listActivitiesRequest{})
+	ue.GET("/api/_version_/fleet/changes/stream", streamChangesEndpoint, streamChangesRequest{})
 
 	ue.POST("/api/_version_/fleet/download_installer/{kind}", getInstallerEndpoint, getInstallerRequest{})
 	ue.HEAD("/api/_version_/fleet/download_installer/{kind}", checkInstallerEndpoint, checkInstallerRequest{})
 
 	ue.GET("/api/_version_/fleet/packs/{id:[0-9]+}/scheduled", getScheduledQueriesInPackEndpoint, getScheduledQueriesInPackRequest{})
-	ue.EndingAtVersion("v1").POST("/api/_version_/fleet/schedule", scheduleQueryEndpoint, scheduleQueryRequest{})
-	ue.StartingAtVersion("2022-04").POST("/api/_version_/fleet/packs/schedule", scheduleQueryEndpoint, scheduleQueryRequest{})
+	ueGitOps.EndingAtVersion("v1").POST("/api/_version_/fleet/schedule", scheduleQueryEndpoint, scheduleQueryRequest{})
+	ueGitOps.StartingAtVersion("2022-04").POST("/api/_version_/fleet/packs/schedule", scheduleQueryEndpoint, scheduleQueryRequest{})
 	ue.GET("/api/_version_/fleet/schedule/{id:[0-9]+}", getScheduledQueryEndpoint, getScheduledQueryRequest{})
-	ue.EndingAtVersion("v1").PATCH("/api/_version_/fleet/schedule/{id:[0-9]+}", modifyScheduledQueryEndpoint, modifyScheduledQueryRequest{})
-	ue.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/packs/schedule/{id:[0-9]+}", modifyScheduledQueryEndpoint, modifyScheduledQueryRequest{})
-	ue.EndingAtVersion("v1").DELETE("/api/_version_/fleet/schedule/{id:[0-9]+}", deleteScheduledQueryEndpoint, deleteScheduledQueryRequest{})
-	ue.StartingAtVersion("2022-04").DELETE("/api/_version_/fleet/packs/schedule/{id:[0-9]+}", deleteScheduledQueryEndpoint, deleteScheduledQueryRequest{})
+	ueGitOps.EndingAtVersion("v1").PATCH("/api/_version_/fleet/schedule/{id:[0-9]+}", modifyScheduledQueryEndpoint, modifyScheduledQueryRequest{})
+	ueGitOps.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/packs/schedule/{id:[0-9]+}", modifyScheduledQueryEndpoint, modifyScheduledQueryRequest{})
+	ueGitOps.EndingAtVersion("v1").DELETE("/api/_version_/fleet/schedule/{id:[0-9]+}", deleteScheduledQueryEndpoint, deleteScheduledQueryRequest{})
+	ueGitOps.StartingAtVersion("2022-04").DELETE("/api/_version_/fleet/packs/schedule/{id:[0-9]+}", deleteScheduledQueryEndpoint, deleteScheduledQueryRequest{})
 
 	ue.EndingAtVersion("v1").GET("/api/_version_/fleet/global/schedule", getGlobalScheduleEndpoint, getGlobalScheduleRequest{})
 	ue.StartingAtVersion("2022-04").GET("/api/_version_/fleet/schedule", getGlobalScheduleEndpoint, getGlobalScheduleRequest{})
-	ue.EndingAtVersion("v1").POST("/api/_version_/fleet/global/schedule", globalScheduleQueryEndpoint, globalScheduleQueryRequest{})
-	ue.StartingAtVersion("2022-04").POST("/api/_version_/fleet/schedule", globalScheduleQueryEndpoint, globalScheduleQueryRequest{})
-	ue.EndingAtVersion("v1").PATCH("/api/_version_/fleet/global/schedule/{id:[0-9]+}", modifyGlobalScheduleEndpoint, modifyGlobalScheduleRequest{})
-	ue.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/schedule/{id:[0-9]+}", modifyGlobalScheduleEndpoint, modifyGlobalScheduleRequest{})
-	ue.EndingAtVersion("v1").DELETE("/api/_version_/fleet/global/schedule/{id:[0-9]+}", deleteGlobalScheduleEndpoint, deleteGlobalScheduleRequest{})
-	ue.StartingAtVersion("2022-04").DELETE("/api/_version_/fleet/schedule/{id:[0-9]+}", deleteGlobalScheduleEndpoint, deleteGlobalScheduleRequest{})
+	ueGitOps.EndingAtVersion("v1").POST("/api/_version_/fleet/global/schedule", globalScheduleQueryEndpoint, globalScheduleQueryRequest{})
+	ueGitOps.StartingAtVersion("2022-04").POST("/api/_version_/fleet/schedule", globalScheduleQueryEndpoint, globalScheduleQueryRequest{})
+	ueGitOps.EndingAtVersion("v1").PATCH("/api/_version_/fleet/global/schedule/{id:[0-9]+}", modifyGlobalScheduleEndpoint, modifyGlobalScheduleRequest{})
+	ueGitOps.StartingAtVersion("2022-04").PATCH("/api/_version_/fleet/schedule/{id:[0-9]+}", modifyGlobalScheduleEndpoint, modifyGlobalScheduleRequest{})
+	ueGitOps.EndingAtVersion("v1").DELETE("/api/_version_/fleet/global/schedule/{id:[0-9]+}", deleteGlobalScheduleEndpoint, deleteGlobalScheduleRequest{})
+	ueGitOps.StartingAtVersion("2022-04").DELETE("/api/_version_/fleet/schedule/{id:[0-9]+}", deleteGlobalScheduleEndpoint, deleteGlobalScheduleRequest{})
 
 	// Alias /api/_version_/fleet/team/ -> /api/_version_/fleet/teams/
 	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/schedule").
@@ -470,18 +615,53 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/scripts/run", runScriptEndpoint, runScriptRequest{})
 	ue.POST("/api/_version_/fleet/scripts/run/sync", runScriptSyncEndpoint, runScriptSyncRequest{})
 	ue.GET("/api/_version_/fleet/scripts/results/{execution_id}", getScriptResultEndpoint, getScriptResultRequest{})
-	ue.POST("/api/_version_/fleet/scripts", createScriptEndpoint, createScriptRequest{})
+	ueGitOps.POST("/api/_version_/fleet/scripts", createScriptEndpoint, createScriptRequest{})
 	ue.GET("/api/_version_/fleet/scripts", listScriptsEndpoint, listScriptsRequest{})
 	ue.GET("/api/_version_/fleet/scripts/{script_id:[0-9]+}", getScriptEndpoint, getScriptRequest{})
-	ue.DELETE("/api/_version_/fleet/scripts/{script_id:[0-9]+}", deleteScriptEndpoint, deleteScriptRequest{})
-	ue.POST("/api/_version_/fleet/scripts/batch", batchSetScriptsEndpoint, batchSetScriptsRequest{})
+	ueGitOps.DELETE("/api/_version_/fleet/scripts/{script_id:[0-9]+}", deleteScriptEndpoint, deleteScriptRequest{})
+	ue.PATCH("/api/_version_/fleet/scripts/{script_id:[0-9]+}", updateScriptSharingEndpoint, updateScriptSharingRequest{})
+	ueGitOps.POST("/api/_version_/fleet/scripts/batch", batchSetScriptsEndpoint, batchSetScriptsRequest{})
+	ue.POST("/api/_version_/fleet/scripts/schedules", createScriptScheduleEndpoint, createScriptScheduleRequest{})
+	ue.GET("/api/_version_/fleet/scripts/schedules", listScriptSchedulesEndpoint, listScriptSchedulesRequest{})
+	ue.DELETE("/api/_version_/fleet/scripts/schedules/{id:[0-9]+}", deleteScriptScheduleEndpoint, deleteScriptScheduleRequest{})
 
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/scripts", getHostScriptDetailsEndpoint, getHostScriptDetailsRequest{})
+
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/log_collections", requestHostLogCollectionEndpoint, requestHostLogCollectionRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/log_collections", listHostLogCollectionsEndpoint, listHostLogCollectionsRequest{})
+	ue.GET("/api/_version_/fleet/log_collections/{execution_id}", getHostLogCollectionEndpoint, getHostLogCollectionRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/file_retrievals", requestHostFileRetrievalEndpoint, requestHostFileRetrievalRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/file_retrievals", listHostFileRetrievalsEndpoint, listHostFileRetrievalsRequest{})
+	ue.GET("/api/_version_/fleet/file_retrievals/{execution_id}", getHostFileRetrievalEndpoint, getHostFileRetrievalRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/pprof_captures", requestHostPprofCaptureEndpoint, requestHostPprofCaptureRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/pprof_captures", listHostPprofCapturesEndpoint, listHostPprofCapturesRequest{})
+	ue.GET("/api/_version_/fleet/pprof_captures/{execution_id}", getHostPprofCaptureEndpoint, getHostPprofCaptureRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/remote_queries", requestHostRemoteQueryEndpoint, requestHostRemoteQueryRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/remote_queries", listHostRemoteQueriesEndpoint, listHostRemoteQueriesRequest{})
+	ue.GET("/api/_version_/fleet/remote_queries/{execution_id}", getHostRemoteQueryEndpoint, getHostRemoteQueryRequest{})
+
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/activities/upcoming", listHostUpcomingActivitiesEndpoint, listHostUpcomingActivitiesRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/activities", listHostPastActivitiesEndpoint, listHostPastActivitiesRequest{})
+	ue.GET("/api/_version_/fleet/hosts/pending_approval", listHostsPendingApprovalEndpoint, nil)
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/approve", approveHostEnrollmentEndpoint, approveHostEnrollmentRequest{})
+	ue.GET("/api/_version_/fleet/hosts/duplicates", listDuplicateHostsEndpoint, nil)
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/merge", mergeHostsEndpoint, mergeHostsRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/lock", lockHostEndpoint, lockHostRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/unlock", unlockHostEndpoint, unlockHostRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/unlock_pin", getHostUnlockPINEndpoint, getHostUnlockPINRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/wipe", wipeHostEndpoint, wipeHostRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/isolate", isolateHostEndpoint, isolateHostRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/unisolate", unisolateHostEndpoint, unisolateHostRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/lost_mode", enableHostLostModeEndpoint, enableHostLostModeRequest{})
+	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}/lost_mode", disableHostLostModeEndpoint, disableHostLostModeRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/lost_mode", getHostLostModeEndpoint, getHostLostModeRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/location", requestHostLocationEndpoint, requestHostLocationRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/location", getHostLocationEndpoint, getHostLocationRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/activation_lock_bypass_code", getHostActivationLockBypassCodeEndpoint, getHostActivationLockBypassCodeRequest{})
+	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}/activation_lock_bypass_code", clearHostActivationLockBypassCodeEndpoint, clearHostActivationLockBypassCodeRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/recovery_lock_password", getHostRecoveryLockPasswordEndpoint, getHostRecoveryLockPasswordRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/firmware_password", setHostFirmwarePasswordEndpoint, setHostFirmwarePasswordRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/firmware_password", getHostFirmwarePasswordEndpoint, getHostFirmwarePasswordRequest{})
 
 	// Only Fleet MDM specific endpoints should be within the root /mdm/ path.
 	// NOTE: remember to update
@@ -491,6 +671,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// endpoints using `neMDM` below in this file.
 	mdmConfiguredMiddleware := mdmconfigured.NewMDMConfigMiddleware(svc)
 	mdmAppleMW := ue.WithCustomMiddleware(mdmConfiguredMiddleware.VerifyAppleMDM())
+	mdmAppleGitOpsMW := mdmAppleMW.WithGitOpsMode()
 
 	// Deprecated: POST /mdm/apple/enqueue is now deprecated, replaced by the
 	// platform-agnostic POST /mdm/commands/run. It is still supported
@@ -508,8 +689,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// replaced by the platform-agnostic /mdm/profiles/... It is still supported
 	// indefinitely for backwards compatibility.
 	mdmAppleMW.GET("/api/_version_/fleet/mdm/apple/profiles/{profile_id:[0-9]+}", getMDMAppleConfigProfileEndpoint, getMDMAppleConfigProfileRequest{})
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/apple/profiles/{profile_id:[0-9]+}", deleteMDMAppleConfigProfileEndpoint, deleteMDMAppleConfigProfileRequest{})
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/profiles", newMDMAppleConfigProfileEndpoint, newMDMAppleConfigProfileRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/apple/profiles/{profile_id:[0-9]+}", deleteMDMAppleConfigProfileEndpoint, deleteMDMAppleConfigProfileRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/apple/profiles", newMDMAppleConfigProfileEndpoint, newMDMAppleConfigProfileRequest{})
 	mdmAppleMW.GET("/api/_version_/fleet/mdm/apple/profiles", listMDMAppleConfigProfilesEndpoint, listMDMAppleConfigProfilesRequest{})
 
 	// Deprecated: GET /mdm/apple/filevault/summary is now deprecated, replaced by the
@@ -524,8 +705,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: POST /mdm/apple/enrollment_profile is now deprecated, replaced by the
 	// POST /enrollment_profiles/automatic endpoint.
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/enrollment_profile", createMDMAppleSetupAssistantEndpoint, createMDMAppleSetupAssistantRequest{})
-	mdmAppleMW.POST("/api/_version_/fleet/enrollment_profiles/automatic", createMDMAppleSetupAssistantEndpoint, createMDMAppleSetupAssistantRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/apple/enrollment_profile", createMDMAppleSetupAssistantEndpoint, createMDMAppleSetupAssistantRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/enrollment_profiles/automatic", createMDMAppleSetupAssistantEndpoint, createMDMAppleSetupAssistantRequest{})
 
 	// Deprecated: GET /mdm/apple/enrollment_profile is now deprecated, replaced by the
 	// GET /enrollment_profiles/automatic endpoint.
@@ -534,8 +715,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: DELETE /mdm/apple/enrollment_profile is now deprecated, replaced by the
 	// DELETE /enrollment_profiles/automatic endpoint.
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/apple/enrollment_profile", deleteMDMAppleSetupAssistantEndpoint, deleteMDMAppleSetupAssistantRequest{})
-	mdmAppleMW.DELETE("/api/_version_/fleet/enrollment_profiles/automatic", deleteMDMAppleSetupAssistantEndpoint, deleteMDMAppleSetupAssistantRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/apple/enrollment_profile", deleteMDMAppleSetupAssistantEndpoint, deleteMDMAppleSetupAssistantRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/enrollment_profiles/automatic", deleteMDMAppleSetupAssistantEndpoint, deleteMDMAppleSetupAssistantRequest{})
 
 	// TODO: are those undocumented endpoints still needed? I think they were only used
 	// by 'fleetctl apple-mdm' sub-commands.
@@ -555,8 +736,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: POST /mdm/bootstrap is now deprecated, replaced by the
 	// POST /bootstrap endpoint.
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
-	mdmAppleMW.POST("/api/_version_/fleet/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
 
 	// Deprecated: GET /mdm/bootstrap/:team_id/metadata is now deprecated, replaced by the
 	// GET /bootstrap/:team_id/metadata endpoint.
@@ -565,8 +746,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: DELETE /mdm/bootstrap/:team_id is now deprecated, replaced by the
 	// DELETE /bootstrap/:team_id endpoint.
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
-	mdmAppleMW.DELETE("/api/_version_/fleet/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
 
 	// Deprecated: GET /mdm/bootstrap/summary is now deprecated, replaced by the
 	// GET /bootstrap/summary endpoint.
@@ -574,11 +755,11 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	mdmAppleMW.GET("/api/_version_/fleet/bootstrap/summary", getMDMAppleBootstrapPackageSummaryEndpoint, getMDMAppleBootstrapPackageSummaryRequest{})
 
 	// Deprecated: POST /mdm/apple/bootstrap is now deprecated, replaced by the platform agnostic /mdm/bootstrap
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/apple/bootstrap", uploadBootstrapPackageEndpoint, uploadBootstrapPackageRequest{})
 	// Deprecated: GET /mdm/apple/bootstrap/:team_id/metadata is now deprecated, replaced by the platform agnostic /mdm/bootstrap/:team_id/metadata
 	mdmAppleMW.GET("/api/_version_/fleet/mdm/apple/bootstrap/{team_id:[0-9]+}/metadata", bootstrapPackageMetadataEndpoint, bootstrapPackageMetadataRequest{})
 	// Deprecated: DELETE /mdm/apple/bootstrap/:team_id is now deprecated, replaced by the platform agnostic /mdm/bootstrap/:team_id
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/apple/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/apple/bootstrap/{team_id:[0-9]+}", deleteBootstrapPackageEndpoint, deleteBootstrapPackageRequest{})
 	// Deprecated: GET /mdm/apple/bootstrap/summary is now deprecated, replaced by the platform agnostic /mdm/bootstrap/summary
 	mdmAppleMW.GET("/api/_version_/fleet/mdm/apple/bootstrap/summary", getMDMAppleBootstrapPackageSummaryEndpoint, getMDMAppleBootstrapPackageSummaryRequest{})
 
@@ -591,6 +772,10 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	mdmAppleMW.POST("/api/_version_/fleet/mdm/hosts/{id:[0-9]+}/lock", deviceLockEndpoint, deviceLockRequest{})
 	mdmAppleMW.POST("/api/_version_/fleet/mdm/hosts/{id:[0-9]+}/wipe", deviceWipeEndpoint, deviceWipeRequest{})
+	mdmAppleMW.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/restart", restartDeviceEndpoint, restartDeviceRequest{})
+	mdmAppleMW.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/shutdown", shutDownDeviceEndpoint, shutDownDeviceRequest{})
+	mdmAppleMW.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/unlock_user_account", unlockUserAccountEndpoint, unlockUserAccountRequest{})
+	mdmAppleMW.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/mdm/repair", repairHostMDMEndpoint, repairHostMDMRequest{})
 
 	// Deprecated: GET /mdm/hosts/:id/profiles is now deprecated, replaced by
 	// GET /hosts/:id/configuration_profiles.
@@ -600,8 +785,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: PATCH /mdm/apple/setup is now deprecated, replaced by the
 	// PATCH /setup_experience endpoint.
-	mdmAppleMW.PATCH("/api/_version_/fleet/mdm/apple/setup", updateMDMAppleSetupEndpoint, updateMDMAppleSetupRequest{})
-	mdmAppleMW.PATCH("/api/_version_/fleet/setup_experience", updateMDMAppleSetupEndpoint, updateMDMAppleSetupRequest{})
+	mdmAppleGitOpsMW.PATCH("/api/_version_/fleet/mdm/apple/setup", updateMDMAppleSetupEndpoint, updateMDMAppleSetupRequest{})
+	mdmAppleGitOpsMW.PATCH("/api/_version_/fleet/setup_experience", updateMDMAppleSetupEndpoint, updateMDMAppleSetupRequest{})
 
 	// Deprecated: GET /mdm/apple is now deprecated, replaced by the
 	// GET /apns endpoint.
@@ -612,8 +797,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: POST /mdm/setup/eula is now deprecated, replaced by the
 	// POST /setup_experience/eula endpoint.
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/setup/eula", createMDMEULAEndpoint, createMDMEULARequest{})
-	mdmAppleMW.POST("/api/_version_/fleet/setup_experience/eula", createMDMEULAEndpoint, createMDMEULARequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/setup/eula", createMDMEULAEndpoint, createMDMEULARequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/setup_experience/eula", createMDMEULAEndpoint, createMDMEULARequest{})
 
 	// Deprecated: GET /mdm/setup/eula/metadata is now deprecated, replaced by the
 	// GET /setup_experience/eula/metadata endpoint.
@@ -622,20 +807,21 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: DELETE /mdm/setup/eula/:token is now deprecated, replaced by the
 	// DELETE /setup_experience/eula/:token endpoint.
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/setup/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
-	mdmAppleMW.DELETE("/api/_version_/fleet/setup_experience/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/setup/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/setup_experience/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
 
 	// Deprecated: POST /mdm/apple/setup/eula is now deprecated, replaced by the platform agnostic /mdm/setup/eula
-	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/setup/eula", createMDMEULAEndpoint, createMDMEULARequest{})
+	mdmAppleGitOpsMW.POST("/api/_version_/fleet/mdm/apple/setup/eula", createMDMEULAEndpoint, createMDMEULARequest{})
 	// Deprecated: GET /mdm/apple/setup/eula/metadata is now deprecated, replaced by the platform agnostic /mdm/setup/eula/metadata
 	mdmAppleMW.GET("/api/_version_/fleet/mdm/apple/setup/eula/metadata", getMDMEULAMetadataEndpoint, getMDMEULAMetadataRequest{})
 	// Deprecated: DELETE /mdm/apple/setup/eula/:token is now deprecated, replaced by the platform agnostic /mdm/setup/eula/:token
-	mdmAppleMW.DELETE("/api/_version_/fleet/mdm/apple/setup/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
+	mdmAppleGitOpsMW.DELETE("/api/_version_/fleet/mdm/apple/setup/eula/{token}", deleteMDMEULAEndpoint, deleteMDMEULARequest{})
 
 	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/profiles/preassign", preassignMDMAppleProfileEndpoint, preassignMDMAppleProfileRequest{})
 	mdmAppleMW.POST("/api/_version_/fleet/mdm/apple/profiles/match", matchMDMApplePreassignmentEndpoint, matchMDMApplePreassignmentRequest{})
 
 	mdmAnyMW := ue.WithCustomMiddleware(mdmConfiguredMiddleware.VerifyAppleOrWindowsMDM())
+	mdmAnyGitOpsMW := mdmAnyMW.WithGitOpsMode()
 
 	// Deprecated: POST /mdm/commands/run is now deprecated, replaced by the
 	// POST /commands/run endpoint.
@@ -660,22 +846,40 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// Deprecated: GET /mdm/hosts/:id/encryption_key is now deprecated, replaced by
 	// GET /hosts/:id/encryption_key.
 	mdmAnyMW.GET("/api/_version_/fleet/mdm/hosts/{id:[0-9]+}/encryption_key", getHostEncryptionKey, getHostEncryptionKeyRequest{})
-	mdmAnyMW.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/encryption_key", getHostEncryptionKey, getHostEncryptionKeyRequest{})
+	// Linux hosts escrow their LUKS key without MDM, the MDM requirements for
+	// macOS and Windows hosts are checked by the endpoint.
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/encryption_key", getHostEncryptionKey, getHostEncryptionKeyRequest{})
 
 	// Deprecated: GET /mdm/profiles/summary is now deprecated, replaced by the
 	// GET /configuration_profiles/summary endpoint.
 	mdmAnyMW.GET("/api/_version_/fleet/mdm/profiles/summary", getMDMProfilesSummaryEndpoint, getMDMProfilesSummaryRequest{})
 	mdmAnyMW.GET("/api/_version_/fleet/configuration_profiles/summary", getMDMProfilesSummaryEndpoint, getMDMProfilesSummaryRequest{})
 
+	// POST /configuration_profiles/preview_targets returns the hosts targeted
+	// by a profile with the given labels, before uploading it.
+	mdmAnyMW.POST("/api/_version_/fleet/configuration_profiles/preview_targets", previewMDMProfileTargetsEndpoint, previewMDMProfileTargetsRequest{})
+
+	// profile redelivery campaigns, must be registered before the
+	// /configuration_profiles/:profile_uuid routes.
+	mdmAnyMW.POST("/api/_version_/fleet/configuration_profiles/redelivery_campaigns", newMDMProfileRedeliveryCampaignEndpoint, newMDMProfileRedeliveryCampaignRequest{})
+	mdmAnyMW.GET("/api/_version_/fleet/configuration_profiles/redelivery_campaigns", listMDMProfileRedeliveryCampaignsEndpoint, listMDMProfileRedeliveryCampaignsRequest{})
+	mdmAnyMW.GET("/api/_version_/fleet/configuration_profiles/redelivery_campaigns/{id:[0-9]+}", getMDMProfileRedeliveryCampaignEndpoint, getMDMProfileRedeliveryCampaignRequest{})
+	mdmAnyMW.PATCH("/api/_version_/fleet/configuration_profiles/redelivery_campaigns/{id:[0-9]+}", modifyMDMProfileRedeliveryCampaignEndpoint, modifyMDMProfileRedeliveryCampaignRequest{})
+
+	// profile staged rollouts
+	mdmAnyMW.GET("/api/_version_/fleet/configuration_profiles/{profile_uuid}/rollout", getMDMProfileRolloutEndpoint, getMDMProfileRolloutRequest{})
+	mdmAnyMW.PATCH("/api/_version_/fleet/configuration_profiles/{profile_uuid}/rollout", modifyMDMProfileRolloutEndpoint, modifyMDMProfileRolloutRequest{})
+
 	// Deprecated: GET /mdm/profiles/:profile_uuid is now deprecated, replaced by
 	// GET /configuration_profiles/:profile_uuid.
 	mdmAnyMW.GET("/api/_version_/fleet/mdm/profiles/{profile_uuid}", getMDMConfigProfileEndpoint, getMDMConfigProfileRequest{})
 	mdmAnyMW.GET("/api/_version_/fleet/configuration_profiles/{profile_uuid}", getMDMConfigProfileEndpoint, getMDMConfigProfileRequest{})
+	mdmAnyMW.PATCH("/api/_version_/fleet/configuration_profiles/{profile_uuid}", updateMDMConfigProfileSharingEndpoint, updateMDMConfigProfileSharingRequest{})
 
 	// Deprecated: DELETE /mdm/profiles/:profile_uuid is now deprecated, replaced by
 	// DELETE /configuration_profiles/:profile_uuid.
-	mdmAnyMW.DELETE("/api/_version_/fleet/mdm/profiles/{profile_uuid}", deleteMDMConfigProfileEndpoint, deleteMDMConfigProfileRequest{})
-	mdmAnyMW.DELETE("/api/_version_/fleet/configuration_profiles/{profile_uuid}", deleteMDMConfigProfileEndpoint, deleteMDMConfigProfileRequest{})
+	mdmAnyGitOpsMW.DELETE("/api/_version_/fleet/mdm/profiles/{profile_uuid}", deleteMDMConfigProfileEndpoint, deleteMDMConfigProfileRequest{})
+	mdmAnyGitOpsMW.DELETE("/api/_version_/fleet/configuration_profiles/{profile_uuid}", deleteMDMConfigProfileEndpoint, deleteMDMConfigProfileRequest{})
 
 	// Deprecated: GET /mdm/profiles is now deprecated, replaced by the
 	// GET /configuration_profiles endpoint.
@@ -684,15 +888,15 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	// Deprecated: POST /mdm/profiles is now deprecated, replaced by the
 	// POST /configuration_profiles endpoint.
-	mdmAnyMW.POST("/api/_version_/fleet/mdm/profiles", newMDMConfigProfileEndpoint, newMDMConfigProfileRequest{})
-	mdmAnyMW.POST("/api/_version_/fleet/configuration_profiles", newMDMConfigProfileEndpoint, newMDMConfigProfileRequest{})
+	mdmAnyGitOpsMW.POST("/api/_version_/fleet/mdm/profiles", newMDMConfigProfileEndpoint, newMDMConfigProfileRequest{})
+	mdmAnyGitOpsMW.POST("/api/_version_/fleet/configuration_profiles", newMDMConfigProfileEndpoint, newMDMConfigProfileRequest{})
 
 	mdmAnyMW.POST("/api/_version_/fleet/hosts/{host_id:[0-9]+}/configuration_profiles/resend/{profile_uuid}", resendHostMDMProfileEndpoint, resendHostMDMProfileRequest{})
 
 	// Deprecated: PATCH /mdm/apple/settings is deprecated, replaced by POST /disk_encryption.
 	// It was only used to set disk encryption.
-	mdmAnyMW.PATCH("/api/_version_/fleet/mdm/apple/settings", updateMDMAppleSettingsEndpoint, updateMDMAppleSettingsRequest{})
-	mdmAnyMW.POST("/api/_version_/fleet/disk_encryption", updateMDMDiskEncryptionEndpoint, updateMDMDiskEncryptionRequest{})
+	mdmAnyGitOpsMW.PATCH("/api/_version_/fleet/mdm/apple/settings", updateMDMAppleSettingsEndpoint, updateMDMAppleSettingsRequest{})
+	mdmAnyGitOpsMW.POST("/api/_version_/fleet/disk_encryption", updateMDMDiskEncryptionEndpoint, updateMDMDiskEncryptionRequest{})
 
 	// the following set of mdm endpoints must always be accessible (even
 	// if MDM is not configured) as it bootstraps the setup of MDM
@@ -712,12 +916,12 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// batch-apply is accessible even though MDM is not enabled, it needs
 	// to support the case where `fleetctl get config`'s output is used as
 	// input to `fleetctl apply`
-	ue.POST("/api/_version_/fleet/mdm/apple/profiles/batch", batchSetMDMAppleProfilesEndpoint, batchSetMDMAppleProfilesRequest{})
+	ueGitOps.POST("/api/_version_/fleet/mdm/apple/profiles/batch", batchSetMDMAppleProfilesEndpoint, batchSetMDMAppleProfilesRequest{})
 
 	// batch-apply is accessible even though MDM is not enabled, it needs
 	// to support the case where `fleetctl get config`'s output is used as
 	// input to `fleetctl apply`
-	ue.POST("/api/_version_/fleet/mdm/profiles/batch", batchSetMDMProfilesEndpoint, batchSetMDMProfilesRequest{})
+	ueGitOps.POST("/api/_version_/fleet/mdm/profiles/batch", batchSetMDMProfilesEndpoint, batchSetMDMProfilesRequest{})
 
 	errorLimiter := ratelimit.NewErrorMiddleware(limitStore)
 
@@ -754,16 +958,18 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 		errorLimiter.Limit("send_device_error", desktopQuota),
 	).POST("/api/_version_/fleet/device/{token}/debug/errors", fleetdError, fleetdErrorRequest{})
 
+	// the Apple MDM requirement is checked by the endpoint, Linux hosts rotate
+	// their LUKS key without MDM.
+	de.WithCustomMiddleware(
+		errorLimiter.Limit("post_device_rotate_encryption_key", desktopQuota),
+	).POST("/api/_version_/fleet/device/{token}/rotate_encryption_key", rotateEncryptionKeyEndpoint, rotateEncryptionKeyRequest{})
+
 	// mdm-related endpoints available via device authentication
 	demdm := de.WithCustomMiddleware(mdmConfiguredMiddleware.VerifyAppleMDM())
 	demdm.WithCustomMiddleware(
 		errorLimiter.Limit("get_device_mdm", desktopQuota),
 	).GET("/api/_version_/fleet/device/{token}/mdm/apple/manual_enrollment_profile", getDeviceMDMManualEnrollProfileEndpoint, getDeviceMDMManualEnrollProfileRequest{})
 
-	demdm.WithCustomMiddleware(
-		errorLimiter.Limit("post_device_rotate_encryption_key", desktopQuota),
-	).POST("/api/_version_/fleet/device/{token}/rotate_encryption_key", rotateEncryptionKeyEndpoint, rotateEncryptionKeyRequest{})
-
 	demdm.WithCustomMiddleware(
 		errorLimiter.Limit("post_device_migrate_mdm", desktopQuota),
 	).POST("/api/_version_/fleet/device/{token}/migrate_mdm", migrateMDMDeviceEndpoint, deviceMigrateMDMRequest{})
@@ -797,7 +1003,19 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// endpoints are POST due to passing the device token in the JSON body.
 	oe.POST("/api/fleet/orbit/scripts/request", getOrbitScriptEndpoint, orbitGetScriptRequest{})
 	oe.POST("/api/fleet/orbit/scripts/result", postOrbitScriptResultEndpoint, orbitPostScriptResultRequest{})
+	oe.POST("/api/fleet/orbit/log_collections/request", getOrbitLogCollectionEndpoint, orbitGetLogCollectionRequest{})
+	oe.POST("/api/fleet/orbit/log_collections/result", postOrbitLogCollectionResultEndpoint, orbitPostLogCollectionResultRequest{})
+	oe.POST("/api/fleet/orbit/file_retrievals/request", getOrbitFileRetrievalEndpoint, orbitGetFileRetrievalRequest{})
+	oe.POST("/api/fleet/orbit/file_retrievals/result", postOrbitFileRetrievalResultEndpoint, orbitPostFileRetrievalResultRequest{})
+	oe.POST("/api/fleet/orbit/pprof_captures/request", getOrbitPprofCaptureEndpoint, orbitGetPprofCaptureRequest{})
+	oe.POST("/api/fleet/orbit/pprof_captures/result", postOrbitPprofCaptureResultEndpoint, orbitPostPprofCaptureResultRequest{})
+	oe.POST("/api/fleet/orbit/remote_queries/request", getOrbitRemoteQueryEndpoint, orbitGetRemoteQueryRequest{})
+	oe.POST("/api/fleet/orbit/remote_queries/result", postOrbitRemoteQueryResultEndpoint, orbitPostRemoteQueryResultRequest{})
+	oe.POST("/api/fleet/orbit/tamper_events", postOrbitTamperEventEndpoint, orbitPostTamperEventRequest{})
+	oe.POST("/api/fleet/orbit/service_recoveries", postOrbitServiceRecoveryEndpoint, orbitPostServiceRecoveryRequest{})
 	oe.PUT("/api/fleet/orbit/device_mapping", putOrbitDeviceMappingEndpoint, orbitPutDeviceMappingRequest{})
+	oe.POST("/api/fleet/orbit/luks_data", postOrbitLUKSEndpoint, orbitPostLUKSRequest{})
+	oe.POST("/api/fleet/orbit/disk_encryption_enforcement", postOrbitLinuxDiskEncryptionEnforcementEndpoint, orbitPostLinuxDiskEncryptionEnforcementRequest{})
 
 	oeWindowsMDM := oe.WithCustomMiddleware(mdmConfiguredMiddleware.VerifyWindowsMDM())
 	oeWindowsMDM.POST("/api/fleet/orbit/disk_encryption_key", postOrbitDiskEncryptionKeyEndpoint, orbitPostDiskEncryptionKeyRequest{})
@@ -896,6 +1114,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	ne.WithCustomMiddleware(limiter.Limit("login", throttled.RateQuota{MaxRate: loginRateLimit, MaxBurst: 9})).
 		POST("/api/_version_/fleet/login", loginEndpoint, loginRequest{})
+	ne.WithCustomMiddleware(limiter.Limit("login", throttled.RateQuota{MaxRate: loginRateLimit, MaxBurst: 9})).
+		POST("/api/_version_/fleet/login/mfa", loginMFAEndpoint, loginMFARequest{})
 
 	// Fleet Sandbox demo login (always errors unless config.server.sandbox_enabled is set)
 	ne.WithCustomMiddleware(limiter.Limit("login", throttled.RateQuota{MaxRate: loginRateLimit, MaxBurst: 9})).
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+/////////////////////////////////////////////////////////////////////////////////
+// List the certificate inventory
+/////////////////////////////////////////////////////////////////////////////////
+
+type listCertificateInventoryRequest struct {
+	fleet.CertificateInventoryListOptions
+}
+
+type listCertificateInventoryResponse struct {
+	Meta         *fleet.PaginationMetadata         `json:"meta"`
+	Count        int                               `json:"count"`
+	Certificates []*fleet.CertificateInventoryItem `json:"certificates"`
+	Err          error                             `json:"error,omitempty"`
+}
+
+func (r listCertificateInventoryResponse) error() error { return r.Err }
+
+func listCertificateInventoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listCertificateInventoryRequest)
+	certs, count, meta, err := svc.ListCertificateInventory(ctx, req.CertificateInventoryListOptions)
+	if err != nil {
+		return listCertificateInventoryResponse{Err: err}, nil
+	}
+	return listCertificateInventoryResponse{Certificates: certs, Count: count, Meta: meta}, nil
+}
+
+var certificateInventoryOrderKeys = map[string]bool{
+	"not_valid_after": true,
+	"hosts_count":     true,
+	"common_name":     true,
+	"sha1":            true,
+}
+
+func (svc *Service) ListCertificateInventory(ctx context.Context, opts fleet.CertificateInventoryListOptions) ([]*fleet.CertificateInventoryItem, int, *fleet.PaginationMetadata, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: opts.TeamID}, fleet.ActionList); err != nil {
+		return nil, 0, nil, err
+	}
+
+	if opts.TeamID != nil && *opts.TeamID != 0 {
+		lic, err := svc.License(ctx)
+		if err != nil {
+			return nil, 0, nil, ctxerr.Wrap(ctx, err, "get license")
+		}
+		if !lic.IsPremium() {
+			return nil, 0, nil, fleet.ErrMissingLicense
+		}
+	}
+
+	if opts.ListOptions.OrderKey == "" {
+		opts.ListOptions.OrderKey = "not_valid_after"
+	}
+	if !certificateInventoryOrderKeys[opts.ListOptions.OrderKey] {
+		return nil, 0, nil, &fleet.BadRequestError{Message: "Invalid order key"}
+	}
+	// always include metadata for the certificate inventory
+	opts.ListOptions.IncludeMetadata = true
+	// cursor-based pagination is not supported for the certificate inventory
+	opts.ListOptions.After = ""
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, 0, nil, fleet.ErrNoContext
+	}
+
+	return svc.ds.ListCertificateInventory(ctx, fleet.TeamFilter{
+		User:            vc.User,
+		IncludeObserver: true,
+		TeamID:          opts.TeamID,
+	}, opts)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// List the certificates of a host
+/////////////////////////////////////////////////////////////////////////////////
+
+type listHostCertificatesRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostCertificatesResponse struct {
+	HostID       uint                     `json:"host_id"`
+	Certificates []*fleet.HostCertificate `json:"certificates"`
+	Err          error                    `json:"error,omitempty"`
+}
+
+func (r listHostCertificatesResponse) error() error { return r.Err }
+
+func listHostCertificatesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostCertificatesRequest)
+	certs, err := svc.ListHostCertificates(ctx, req.ID)
+	if err != nil {
+		return listHostCertificatesResponse{Err: err}, nil
+	}
+	return listHostCertificatesResponse{HostID: req.ID, Certificates: certs}, nil
+}
+
+func (svc *Service) ListHostCertificates(ctx context.Context, hostID uint) ([]*fleet.HostCertificate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostCertificates(ctx, hostID)
+}
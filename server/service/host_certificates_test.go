@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCertificateInventory(t *testing.T) {
+	ds := new(mock.Store)
+
+	var gotFilter fleet.TeamFilter
+	var gotOpts fleet.CertificateInventoryListOptions
+	ds.ListCertificateInventoryFunc = func(ctx context.Context, filter fleet.TeamFilter, opts fleet.CertificateInventoryListOptions) ([]*fleet.CertificateInventoryItem, int, *fleet.PaginationMetadata, error) {
+		gotFilter, gotOpts = filter, opts
+		return []*fleet.CertificateInventoryItem{{SHA1: "a", HostsCount: 2}}, 1, &fleet.PaginationMetadata{}, nil
+	}
+
+	t.Run("free", func(t *testing.T) {
+		svc, ctx := newTestService(t, ds, nil, nil)
+
+		certs, count, _, err := svc.ListCertificateInventory(test.UserContext(ctx, test.UserObserver), fleet.CertificateInventoryListOptions{})
+		require.NoError(t, err)
+		require.Len(t, certs, 1)
+		require.Equal(t, 1, count)
+		require.True(t, gotFilter.IncludeObserver)
+		require.Equal(t, test.UserObserver, gotFilter.User)
+		require.Equal(t, "not_valid_after", gotOpts.ListOptions.OrderKey)
+		require.True(t, gotOpts.ListOptions.IncludeMetadata)
+
+		// hosts in no team are available without a license
+		_, _, _, err = svc.ListCertificateInventory(test.UserContext(ctx, test.UserAdmin), fleet.CertificateInventoryListOptions{TeamID: ptr.Uint(0)})
+		require.NoError(t, err)
+		require.Equal(t, ptr.Uint(0), gotFilter.TeamID)
+
+		_, _, _, err = svc.ListCertificateInventory(test.UserContext(ctx, test.UserAdmin), fleet.CertificateInventoryListOptions{TeamID: ptr.Uint(1)})
+		require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+		_, _, _, err = svc.ListCertificateInventory(test.UserContext(ctx, test.UserAdmin), fleet.CertificateInventoryListOptions{
+			ListOptions: fleet.ListOptions{OrderKey: "subject"},
+		})
+		require.ErrorContains(t, err, "Invalid order key")
+
+		_, _, _, err = svc.ListCertificateInventory(test.UserContext(ctx, test.UserNoRoles), fleet.CertificateInventoryListOptions{})
+		checkAuthErr(t, true, err)
+	})
+
+	t.Run("premium", func(t *testing.T) {
+		svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+		_, _, _, err := svc.ListCertificateInventory(test.UserContext(ctx, test.UserTeamObserverTeam1), fleet.CertificateInventoryListOptions{
+			TeamID:      ptr.Uint(1),
+			ListOptions: fleet.ListOptions{OrderKey: "hosts_count", OrderDirection: fleet.OrderDescending},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ptr.Uint(1), gotFilter.TeamID)
+		require.Equal(t, "hosts_count", gotOpts.ListOptions.OrderKey)
+	})
+}
+
+func TestListHostCertificates(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		if id == 2 {
+			return &fleet.Host{ID: id, TeamID: ptr.Uint(2)}, nil
+		}
+		return &fleet.Host{ID: id, TeamID: ptr.Uint(1)}, nil
+	}
+	ds.ListHostCertificatesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostCertificate, error) {
+		return []*fleet.HostCertificate{{HostID: hostID, SHA1: "a", Origin: fleet.HostCertificateOriginMDM, IsIdentity: true}}, nil
+	}
+
+	certs, err := svc.ListHostCertificates(test.UserContext(ctx, test.UserObserver), 1)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, fleet.HostCertificateOriginMDM, certs[0].Origin)
+
+	// team users can only see the certificates of the hosts of their teams
+	_, err = svc.ListHostCertificates(test.UserContext(ctx, test.UserTeamObserverTeam1), 1)
+	require.NoError(t, err)
+	_, err = svc.ListHostCertificates(test.UserContext(ctx, test.UserTeamObserverTeam1), 2)
+	checkAuthErr(t, true, err)
+	_, err = svc.ListHostCertificates(test.UserContext(ctx, test.UserNoRoles), 1)
+	checkAuthErr(t, true, err)
+}
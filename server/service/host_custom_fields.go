@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Update host notes and custom fields
+////////////////////////////////////////////////////////////////////////////////
+
+type updateHostCustomFieldsRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.HostCustomFieldsPayload
+}
+
+type updateHostCustomFieldsResponse struct {
+	*fleet.HostCustomFields
+	Err error `json:"error,omitempty"`
+}
+
+func (r updateHostCustomFieldsResponse) error() error { return r.Err }
+
+func updateHostCustomFieldsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*updateHostCustomFieldsRequest)
+	res, err := svc.UpdateHostCustomFields(ctx, req.ID, req.HostCustomFieldsPayload)
+	if err != nil {
+		return updateHostCustomFieldsResponse{Err: err}, nil
+	}
+	return updateHostCustomFieldsResponse{HostCustomFields: res}, nil
+}
+
+func (svc *Service) UpdateHostCustomFields(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) (*fleet.HostCustomFields, error) {
+	// First ensure the user has access to list hosts, then check the specific
+	// host once team_id is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if err := payload.Validate(appConfig.HostCustomFields); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate host custom fields")
+	}
+
+	if err := svc.saveHostCustomFields(ctx, host, payload); err != nil {
+		return nil, err
+	}
+	return svc.ds.GetHostCustomFields(ctx, host.ID)
+}
+
+// saveHostCustomFields saves the validated payload and records the activity.
+func (svc *Service) saveHostCustomFields(ctx context.Context, host *fleet.Host, payload fleet.HostCustomFieldsPayload) error {
+	if payload.Notes == nil && len(payload.CustomFields) == 0 {
+		return nil
+	}
+
+	if err := svc.ds.UpdateHostCustomFields(ctx, host.ID, payload); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host custom fields")
+	}
+
+	fields := make([]string, 0, len(payload.CustomFields))
+	for name := range payload.CustomFields {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedHostCustomFields{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		NotesEdited:     payload.Notes != nil,
+		CustomFields:    fields,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for edited host custom fields")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Apply host custom fields spec
+////////////////////////////////////////////////////////////////////////////////
+
+type applyHostCustomFieldsSpecRequest struct {
+	Spec *fleet.HostCustomFieldsSpec `json:"spec"`
+}
+
+type applyHostCustomFieldsSpecResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r applyHostCustomFieldsSpecResponse) error() error { return r.Err }
+
+func applyHostCustomFieldsSpecEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*applyHostCustomFieldsSpecRequest)
+	var spec fleet.HostCustomFieldsSpec
+	if req.Spec != nil {
+		spec = *req.Spec
+	}
+	if err := svc.ApplyHostCustomFieldsSpec(ctx, spec); err != nil {
+		return applyHostCustomFieldsSpecResponse{Err: err}, nil
+	}
+	return applyHostCustomFieldsSpecResponse{}, nil
+}
+
+func (svc *Service) ApplyHostCustomFieldsSpec(ctx context.Context, spec fleet.HostCustomFieldsSpec) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+
+	// validate everything before updating any host, so that the spec is
+	// either fully applied or not at all (unless a database error occurs).
+	identifiers := make([]string, 0, len(spec.Hosts))
+	for identifier := range spec.Hosts {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	hosts := make([]*fleet.Host, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		host, err := svc.ds.HostByIdentifier(ctx, identifier)
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				return ctxerr.Wrap(ctx, &fleet.BadRequestError{
+					Message:     fmt.Sprintf("host %q not found", identifier),
+					InternalErr: err,
+				})
+			}
+			return ctxerr.Wrap(ctx, err, "get host by identifier")
+		}
+		if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+			return err
+		}
+
+		payload := spec.Hosts[identifier]
+		if payload == nil {
+			payload = &fleet.HostCustomFieldsPayload{}
+			spec.Hosts[identifier] = payload
+		}
+		if err := payload.Validate(appConfig.HostCustomFields); err != nil {
+			return ctxerr.Wrapf(ctx, err, "validate custom fields of host %q", identifier)
+		}
+		hosts = append(hosts, host)
+	}
+
+	for i, host := range hosts {
+		if err := svc.saveHostCustomFields(ctx, host, *spec.Hosts[identifiers[i]]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
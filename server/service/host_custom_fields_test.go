@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateHostCustomFields(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostCustomFields: []fleet.HostCustomFieldDefinition{
+			{Name: "owner", Type: fleet.HostCustomFieldTypeString},
+			{Name: "cost", Type: fleet.HostCustomFieldTypeNumber},
+		}}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, TeamID: ptr.Uint(1), Hostname: "h1"}, nil
+	}
+	var gotPayload fleet.HostCustomFieldsPayload
+	ds.UpdateHostCustomFieldsFunc = func(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
+		gotPayload = payload
+		return nil
+	}
+	ds.GetHostCustomFieldsFunc = func(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
+		return &fleet.HostCustomFields{HostID: hostID, Notes: "n", CustomFields: fleet.HostCustomFieldValues{"owner": "alice"}}, nil
+	}
+	var gotActivity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		gotActivity = activity
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team maintainer", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, false},
+		{"other team maintainer", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			_, err := svc.UpdateHostCustomFields(ctx, 1, fleet.HostCustomFieldsPayload{Notes: ptr.String("n")})
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	res, err := svc.UpdateHostCustomFields(ctx, 1, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"owner": ptr.String(" alice "), "cost": nil},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "alice", res.CustomFields["owner"])
+	require.Nil(t, gotPayload.Notes)
+	require.Equal(t, "alice", *gotPayload.CustomFields["owner"])
+	require.Equal(t, fleet.ActivityTypeEditedHostCustomFields{
+		HostID:          1,
+		HostDisplayName: "h1",
+		CustomFields:    []string{"cost", "owner"},
+	}, gotActivity)
+
+	// invalid values are rejected
+	ds.UpdateHostCustomFieldsFuncInvoked = false
+	_, err = svc.UpdateHostCustomFields(ctx, 1, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"cost": ptr.String("ten")},
+	})
+	require.ErrorContains(t, err, `the value of "cost" must be a number`)
+	_, err = svc.UpdateHostCustomFields(ctx, 1, fleet.HostCustomFieldsPayload{
+		CustomFields: map[string]*string{"nope": ptr.String("x")},
+	})
+	require.ErrorContains(t, err, `custom field "nope" is not defined`)
+	require.False(t, ds.UpdateHostCustomFieldsFuncInvoked)
+}
+
+func TestApplyHostCustomFieldsSpec(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostCustomFields: []fleet.HostCustomFieldDefinition{
+			{Name: "owner", Type: fleet.HostCustomFieldTypeString},
+		}}, nil
+	}
+	hostsByIdentifier := map[string]*fleet.Host{
+		"h1":     {ID: 1, Hostname: "h1"},
+		"uuid-2": {ID: 2, Hostname: "h2"},
+	}
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		h, ok := hostsByIdentifier[identifier]
+		if !ok {
+			return nil, newNotFoundError()
+		}
+		return h, nil
+	}
+	updated := make(map[uint]fleet.HostCustomFieldsPayload)
+	ds.UpdateHostCustomFieldsFunc = func(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
+		updated[hostID] = payload
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	// nothing is applied if any of the hosts is invalid
+	err := svc.ApplyHostCustomFieldsSpec(ctx, fleet.HostCustomFieldsSpec{Hosts: map[string]*fleet.HostCustomFieldsPayload{
+		"h1":   {Notes: ptr.String("n1")},
+		"nope": {Notes: ptr.String("n2")},
+	}})
+	require.ErrorContains(t, err, `host "nope" not found`)
+	err = svc.ApplyHostCustomFieldsSpec(ctx, fleet.HostCustomFieldsSpec{Hosts: map[string]*fleet.HostCustomFieldsPayload{
+		"h1":     {Notes: ptr.String("n1")},
+		"uuid-2": {CustomFields: map[string]*string{"cost": ptr.String("1")}},
+	}})
+	require.ErrorContains(t, err, `custom field "cost" is not defined`)
+	require.Empty(t, updated)
+
+	err = svc.ApplyHostCustomFieldsSpec(ctx, fleet.HostCustomFieldsSpec{Hosts: map[string]*fleet.HostCustomFieldsPayload{
+		"h1":     {Notes: ptr.String("n1")},
+		"uuid-2": {CustomFields: map[string]*string{"owner": ptr.String("bob")}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	require.Equal(t, "n1", *updated[1].Notes)
+	require.Equal(t, "bob", *updated[2].CustomFields["owner"])
+}
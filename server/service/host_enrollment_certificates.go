@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/clientcert"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// verifyEnrollCredentials returns the enroll secret that the host enrolls
+// with. If no valid enroll secret is provided and client certificate
+// enrollment is enabled, the verified TLS client certificate of the request
+// replaces it: the host enrolls in the team of the host that the certificate
+// is bound to, or with no team for a new certificate. The returned
+// certificate, if any, must be recorded once the host is enrolled.
+func (svc *Service) verifyEnrollCredentials(ctx context.Context, enrollSecret, hardwareUUID string) (*fleet.EnrollSecret, *x509.Certificate, error) {
+	secret, err := svc.ds.VerifyEnrollSecret(ctx, enrollSecret)
+	if err == nil {
+		return secret, nil, nil
+	}
+
+	cert := clientcert.FromContext(ctx)
+	if !svc.config.Server.ClientCertificateEnrollment || cert == nil || !fleet.IsNotFound(err) {
+		return nil, nil, err
+	}
+
+	// the hardware UUID is what binds the certificate to a single host
+	if hardwareUUID == "" {
+		return nil, nil, fleet.NewAuthFailedError("client certificate enrollment requires the host hardware UUID")
+	}
+
+	fingerprint := fleet.CertificateSHA256(cert)
+	logging.WithExtras(ctx, "client_certificate_sha256", fingerprint)
+
+	var teamID *uint
+	bound, err := svc.ds.GetHostEnrollmentCertificateBySHA256(ctx, fingerprint)
+	switch {
+	case fleet.IsNotFound(err):
+		// first enrollment with this certificate
+	case err != nil:
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host enrollment certificate")
+	case bound.RevokedAt != nil:
+		return nil, nil, fleet.NewAuthFailedError("client certificate was revoked")
+	case bound.HostUUID != "" && bound.HostUUID != hardwareUUID:
+		return nil, nil, fleet.NewAuthFailedError("client certificate is bound to another host")
+	default:
+		teamID = bound.HostTeamID
+	}
+	return &fleet.EnrollSecret{TeamID: teamID}, cert, nil
+}
+
+// recordEnrollCertificate binds the client certificate that the host enrolled
+// with to the host, if it did not enroll with an enroll secret.
+func (svc *Service) recordEnrollCertificate(ctx context.Context, hostID uint, cert *x509.Certificate) error {
+	if cert == nil {
+		return nil
+	}
+	return svc.ds.RecordHostEnrollmentCertificate(ctx, fleet.NewHostEnrollmentCertificate(hostID, cert))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host enrollment certificates
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostEnrollmentCertificatesRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostEnrollmentCertificatesResponse struct {
+	HostID       uint                               `json:"host_id"`
+	Certificates []*fleet.HostEnrollmentCertificate `json:"enrollment_certificates"`
+	Err          error                              `json:"error,omitempty"`
+}
+
+func (r listHostEnrollmentCertificatesResponse) error() error { return r.Err }
+
+func listHostEnrollmentCertificatesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostEnrollmentCertificatesRequest)
+	certs, err := svc.ListHostEnrollmentCertificates(ctx, req.ID)
+	if err != nil {
+		return listHostEnrollmentCertificatesResponse{Err: err}, nil
+	}
+	return listHostEnrollmentCertificatesResponse{HostID: req.ID, Certificates: certs}, nil
+}
+
+func (svc *Service) ListHostEnrollmentCertificates(ctx context.Context, hostID uint) ([]*fleet.HostEnrollmentCertificate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostEnrollmentCertificates(ctx, hostID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Revoke host enrollment certificates
+////////////////////////////////////////////////////////////////////////////////
+
+type revokeHostEnrollmentCertificatesRequest struct {
+	ID uint `url:"id"`
+}
+
+type revokeHostEnrollmentCertificatesResponse struct {
+	HostID  uint  `json:"host_id"`
+	Revoked int   `json:"revoked"`
+	Err     error `json:"error,omitempty"`
+}
+
+func (r revokeHostEnrollmentCertificatesResponse) error() error { return r.Err }
+
+func revokeHostEnrollmentCertificatesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*revokeHostEnrollmentCertificatesRequest)
+	revoked, err := svc.RevokeHostEnrollmentCertificates(ctx, req.ID)
+	if err != nil {
+		return revokeHostEnrollmentCertificatesResponse{Err: err}, nil
+	}
+	return revokeHostEnrollmentCertificatesResponse{HostID: req.ID, Revoked: revoked}, nil
+}
+
+func (svc *Service) RevokeHostEnrollmentCertificates(ctx context.Context, hostID uint) (int, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return 0, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return 0, err
+	}
+
+	revoked, err := svc.ds.RevokeHostEnrollmentCertificates(ctx, hostID)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "revoke host enrollment certificates")
+	}
+	if revoked == 0 {
+		return 0, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_id", "Host has no active enrollment certificate.").WithStatus(http.StatusConflict))
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return 0, fleet.ErrNoContext
+	}
+	if err := svc.ds.NewActivity(ctx, vc.User, fleet.ActivityTypeRevokedHostEnrollmentCertificates{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		Count:           revoked,
+	}); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "create activity for revoked host enrollment certificates")
+	}
+	return revoked, nil
+}
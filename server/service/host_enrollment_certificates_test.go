@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/clientcert"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEnrollCertificate(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestEnrollWithClientCertificate(t *testing.T) {
+	ds := new(mock.Store)
+	cert := newTestEnrollCertificate(t, "host-1")
+	otherCert := newTestEnrollCertificate(t, "host-2")
+
+	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
+		if secret == "valid_secret" {
+			return &fleet.EnrollSecret{Secret: secret, TeamID: ptr.Uint(3)}, nil
+		}
+		return nil, newTestNotFoundError()
+	}
+	var bound map[string]*fleet.HostEnrollmentCertificate
+	ds.GetHostEnrollmentCertificateBySHA256Func = func(ctx context.Context, sha256 string) (*fleet.HostEnrollmentCertificate, error) {
+		if c, ok := bound[sha256]; ok {
+			return c, nil
+		}
+		return nil, newTestNotFoundError()
+	}
+	var recorded []*fleet.HostEnrollmentCertificate
+	ds.RecordHostEnrollmentCertificateFunc = func(ctx context.Context, cert *fleet.HostEnrollmentCertificate) error {
+		recorded = append(recorded, cert)
+		return nil
+	}
+	var enrolledTeamID *uint
+	ds.EnrollHostFunc = func(ctx context.Context, isMDMEnabled bool, osqueryHostId, hUUID, hSerial, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+		enrolledTeamID = teamID
+		return &fleet.Host{ID: 1, OsqueryHostID: &osqueryHostId, NodeKey: &nodeKey, UUID: hUUID}, nil
+	}
+	ds.UpdateHostFunc = func(ctx context.Context, host *fleet.Host) error {
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	reset := func() {
+		bound = map[string]*fleet.HostEnrollmentCertificate{}
+		recorded = nil
+		enrolledTeamID = nil
+		ds.RecordHostEnrollmentCertificateFuncInvoked = false
+	}
+
+	details := map[string]map[string]string{"system_info": {"uuid": "uuid-1"}}
+
+	cfg := config.TestConfig()
+	cfg.Server.ClientCertificateEnrollment = true
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil)
+	certCtx := clientcert.NewContext(ctx, cert)
+
+	t.Run("enroll secret takes precedence", func(t *testing.T) {
+		reset()
+		_, err := svc.EnrollAgent(certCtx, "valid_secret", "host1", details)
+		require.NoError(t, err)
+		require.Equal(t, ptr.Uint(3), enrolledTeamID)
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+	})
+
+	t.Run("no secret nor certificate", func(t *testing.T) {
+		reset()
+		_, err := svc.EnrollAgent(ctx, "", "host1", details)
+		require.Error(t, err)
+		_, err = svc.EnrollOrbit(ctx, fleet.OrbitHostInfo{HardwareUUID: "uuid-1"}, "")
+		require.Error(t, err)
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+	})
+
+	t.Run("new certificate", func(t *testing.T) {
+		reset()
+		nodeKey, err := svc.EnrollAgent(certCtx, "", "host1", details)
+		require.NoError(t, err)
+		require.NotEmpty(t, nodeKey)
+		require.Nil(t, enrolledTeamID)
+		require.Len(t, recorded, 1)
+		require.Equal(t, uint(1), recorded[0].HostID)
+		require.Equal(t, fleet.CertificateSHA256(cert), recorded[0].SHA256)
+		require.Equal(t, "CN=host-1", recorded[0].Subject)
+	})
+
+	t.Run("hardware uuid is required", func(t *testing.T) {
+		reset()
+		_, err := svc.EnrollAgent(certCtx, "", "host1", nil)
+		require.ErrorContains(t, err, "requires the host hardware UUID")
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+	})
+
+	t.Run("certificate bound to the same host", func(t *testing.T) {
+		reset()
+		bound[fleet.CertificateSHA256(cert)] = &fleet.HostEnrollmentCertificate{HostID: 1, HostUUID: "uuid-1", HostTeamID: ptr.Uint(5)}
+		_, err := svc.EnrollAgent(certCtx, "", "host1", details)
+		require.NoError(t, err)
+		// the host keeps its team
+		require.Equal(t, ptr.Uint(5), enrolledTeamID)
+	})
+
+	t.Run("certificate bound to another host", func(t *testing.T) {
+		reset()
+		bound[fleet.CertificateSHA256(cert)] = &fleet.HostEnrollmentCertificate{HostID: 2, HostUUID: "uuid-2"}
+		_, err := svc.EnrollAgent(certCtx, "", "host1", details)
+		require.ErrorContains(t, err, "bound to another host")
+		_, err = svc.EnrollOrbit(certCtx, fleet.OrbitHostInfo{HardwareUUID: "uuid-1"}, "")
+		var authErr *fleet.AuthFailedError
+		require.ErrorAs(t, err, &authErr)
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+
+		// another certificate can still enroll
+		_, err = svc.EnrollAgent(clientcert.NewContext(ctx, otherCert), "", "host1", details)
+		require.NoError(t, err)
+	})
+
+	t.Run("revoked certificate", func(t *testing.T) {
+		reset()
+		bound[fleet.CertificateSHA256(cert)] = &fleet.HostEnrollmentCertificate{HostID: 1, HostUUID: "uuid-1", RevokedAt: ptr.Time(time.Now())}
+		_, err := svc.EnrollAgent(certCtx, "", "host1", details)
+		require.ErrorContains(t, err, "revoked")
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+	})
+
+	t.Run("certificate enrollment disabled", func(t *testing.T) {
+		reset()
+		svc, ctx := newTestService(t, ds, nil, nil)
+		_, err := svc.EnrollAgent(clientcert.NewContext(ctx, cert), "", "host1", details)
+		require.Error(t, err)
+		require.False(t, ds.RecordHostEnrollmentCertificateFuncInvoked)
+	})
+}
+
+func TestRevokeHostEnrollmentCertificates(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, TeamID: ptr.Uint(id), Hostname: "foo"}, nil
+	}
+	revoked := 2
+	ds.RevokeHostEnrollmentCertificatesFunc = func(ctx context.Context, hostID uint) (int, error) {
+		return revoked, nil
+	}
+	ds.ListHostEnrollmentCertificatesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostEnrollmentCertificate, error) {
+		return []*fleet.HostEnrollmentCertificate{{HostID: hostID, SHA256: "a"}}, nil
+	}
+	var activity *fleet.ActivityTypeRevokedHostEnrollmentCertificates
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, details fleet.ActivityDetails) error {
+		a := details.(fleet.ActivityTypeRevokedHostEnrollmentCertificates)
+		activity = &a
+		return nil
+	}
+
+	certs, err := svc.ListHostEnrollmentCertificates(test.UserContext(ctx, test.UserTeamObserverTeam1), 1)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+
+	// observers cannot revoke
+	_, err = svc.RevokeHostEnrollmentCertificates(test.UserContext(ctx, test.UserObserver), 1)
+	checkAuthErr(t, true, err)
+	require.False(t, ds.RevokeHostEnrollmentCertificatesFuncInvoked)
+
+	// team maintainers can only revoke the certificates of their team's hosts
+	_, err = svc.RevokeHostEnrollmentCertificates(test.UserContext(ctx, test.UserTeamMaintainerTeam1), 2)
+	checkAuthErr(t, true, err)
+
+	n, err := svc.RevokeHostEnrollmentCertificates(test.UserContext(ctx, test.UserTeamMaintainerTeam1), 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NotNil(t, activity)
+	require.Equal(t, uint(1), activity.HostID)
+	require.Equal(t, 2, activity.Count)
+
+	revoked = 0
+	_, err = svc.RevokeHostEnrollmentCertificates(test.UserContext(ctx, test.UserAdmin), 1)
+	var statusErr interface{ Status() int }
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusConflict, statusErr.Status())
+}
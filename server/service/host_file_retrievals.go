@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Request host file retrieval
+////////////////////////////////////////////////////////////////////////////////
+
+type requestHostFileRetrievalRequest struct {
+	HostID uint `url:"id"`
+	fleet.HostFileRetrievalPayload
+}
+
+type requestHostFileRetrievalResponse struct {
+	FileRetrieval *fleet.HostFileRetrieval `json:"file_retrieval,omitempty"`
+	Err           error                    `json:"error,omitempty"`
+}
+
+func (r requestHostFileRetrievalResponse) error() error { return r.Err }
+func (r requestHostFileRetrievalResponse) Status() int  { return http.StatusAccepted }
+
+func requestHostFileRetrievalEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*requestHostFileRetrievalRequest)
+	req.HostFileRetrievalPayload.HostID = req.HostID
+	fr, err := svc.RequestHostFileRetrieval(ctx, &req.HostFileRetrievalPayload)
+	if err != nil {
+		return requestHostFileRetrievalResponse{Err: err}, nil
+	}
+	return requestHostFileRetrievalResponse{FileRetrieval: fr}, nil
+}
+
+func (svc *Service) RequestHostFileRetrieval(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error) {
+	// must load the host to get the team to authorize with the proper team id
+	// and to check the path against the team's allowed paths.
+	host, err := svc.ds.Host(ctx, payload.HostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had access to retrieve files (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostFileRetrieval{}, fleet.ActionWrite); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostFileRetrieval{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the file is read by fleetd, using the same capability as scripts as it
+	// gives access to the host's filesystem.
+	if host.OrbitNodeKey == nil || *host.OrbitNodeKey == "" {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostFileRetrievalFleetdRequiredErrMsg), http.StatusUnprocessableEntity)
+	}
+	if host.ScriptsEnabled != nil && !*host.ScriptsEnabled {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostFileRetrievalOrbitDisabledErrMsg), http.StatusUnprocessableEntity)
+	}
+
+	if payload.Path == "" {
+		return nil, fleet.NewInvalidArgumentError("path", "A path is required.")
+	}
+	settings, err := svc.fileRetrievalSettings(ctx, host.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Allows(payload.Path, host.FleetPlatform()) {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostFileRetrievalPathNotAllowedErrMsg), http.StatusForbidden)
+	}
+
+	payload.NotifyEndUser = settings.NotifyEndUser
+	if user := authz.UserFromContext(ctx); user != nil {
+		payload.UserID = &user.ID
+	}
+	fr, err := svc.ds.NewHostFileRetrieval(ctx, payload)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create host file retrieval")
+	}
+	fr.TeamID = host.TeamID
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeRequestedHostFileRetrieval{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		ExecutionID:     fr.ExecutionID,
+		Path:            fr.Path,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for host file retrieval")
+	}
+	return fr, nil
+}
+
+// fileRetrievalSettings returns the file retrieval settings of the team, or
+// the global settings if teamID is nil.
+func (svc *Service) fileRetrievalSettings(ctx context.Context, teamID *uint) (fleet.FileRetrievalSettings, error) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return fleet.FileRetrievalSettings{}, ctxerr.Wrap(ctx, err, "get app config")
+	}
+
+	if teamID != nil {
+		tm, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return fleet.FileRetrievalSettings{}, ctxerr.Wrap(ctx, err, "get team")
+		}
+		tm.ApplyInheritedSettings(appConfig)
+		return tm.Config.FileRetrieval, nil
+	}
+	return appConfig.FileRetrieval, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host file retrievals
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostFileRetrievalsRequest struct {
+	HostID uint `url:"id"`
+}
+
+type listHostFileRetrievalsResponse struct {
+	FileRetrievals []*fleet.HostFileRetrieval `json:"file_retrievals"`
+	Err            error                      `json:"error,omitempty"`
+}
+
+func (r listHostFileRetrievalsResponse) error() error { return r.Err }
+
+func listHostFileRetrievalsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostFileRetrievalsRequest)
+	frs, err := svc.ListHostFileRetrievals(ctx, req.HostID)
+	if err != nil {
+		return listHostFileRetrievalsResponse{Err: err}, nil
+	}
+	if frs == nil {
+		frs = []*fleet.HostFileRetrieval{} // return empty json array instead of json null
+	}
+	return listHostFileRetrievalsResponse{FileRetrievals: frs}, nil
+}
+
+func (svc *Service) ListHostFileRetrievals(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had global access (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostFileRetrieval{}, fleet.ActionRead); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostFileRetrieval{TeamID: host.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	frs, err := svc.ds.ListHostFileRetrievals(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host file retrievals")
+	}
+	for _, fr := range frs {
+		fr.TeamID = host.TeamID
+	}
+	return frs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host file retrieval
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostFileRetrievalRequest struct {
+	ExecutionID string `url:"execution_id"`
+	Alt         string `query:"alt,optional"`
+}
+
+type getHostFileRetrievalResponse struct {
+	*fleet.HostFileRetrieval
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostFileRetrievalResponse) error() error { return r.Err }
+
+func getHostFileRetrievalEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostFileRetrievalRequest)
+
+	downloadRequested := req.Alt == "media"
+	fr, data, err := svc.GetHostFileRetrieval(ctx, req.ExecutionID, downloadRequested)
+	if err != nil {
+		return getHostFileRetrievalResponse{Err: err}, nil
+	}
+
+	if downloadRequested {
+		return downloadFileResponse{
+			content:     data,
+			filename:    fr.Filename(),
+			contentType: "application/octet-stream",
+		}, nil
+	}
+	return getHostFileRetrievalResponse{HostFileRetrieval: fr}, nil
+}
+
+func (svc *Service) GetHostFileRetrieval(ctx context.Context, execID string, downloadRequested bool) (*fleet.HostFileRetrieval, []byte, error) {
+	fr, err := svc.ds.GetHostFileRetrieval(ctx, execID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostFileRetrieval{}, fleet.ActionRead); err != nil {
+				return nil, nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host file retrieval")
+	}
+
+	// authorize with the team of the host, if the host was deleted since the
+	// request, only global users can access the file.
+	host, err := svc.ds.HostLite(ctx, fr.HostID)
+	if err != nil && !fleet.IsNotFound(err) {
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if host != nil {
+		fr.TeamID = host.TeamID
+	}
+	if err := svc.authz.Authorize(ctx, fr, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+
+	if !downloadRequested {
+		return fr, nil, nil
+	}
+
+	if fr.Status != fleet.HostFileRetrievalStatusCompleted {
+		return nil, nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't download file. The file was not retrieved from the host.",
+		})
+	}
+	data, err := svc.ds.GetHostFileRetrievalData(ctx, execID)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host file retrieval data")
+	}
+
+	// every download of a retrieved file is recorded in the activity feed.
+	var hostDisplayName string
+	if host != nil {
+		hostDisplayName = host.DisplayName()
+	}
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDownloadedHostFile{
+		HostID:          fr.HostID,
+		HostDisplayName: hostDisplayName,
+		ExecutionID:     fr.ExecutionID,
+		Path:            fr.Path,
+	}); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "create activity for host file download")
+	}
+	return fr, data, nil
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostFileRetrievalsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		noTeamHostID = 1
+		team1HostID  = 2
+	)
+	hosts := map[uint]*fleet.Host{
+		noTeamHostID: {ID: noTeamHostID, Platform: "darwin", OrbitNodeKey: ptr.String("abc")},
+		team1HostID:  {ID: team1HostID, Platform: "darwin", OrbitNodeKey: ptr.String("def"), TeamID: ptr.Uint(1)},
+	}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	settings := fleet.FileRetrievalSettings{AllowedPaths: []string{"/var/log/"}}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{FileRetrieval: settings}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{FileRetrieval: settings}}, nil
+	}
+	ds.NewHostFileRetrievalFunc = func(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error) {
+		return &fleet.HostFileRetrieval{HostID: payload.HostID, ExecutionID: "x", Path: payload.Path}, nil
+	}
+	ds.ListHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+		return nil, nil
+	}
+	ds.GetHostFileRetrievalFunc = func(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error) {
+		hostID := uint(noTeamHostID)
+		if execID == "team1" {
+			hostID = team1HostID
+		}
+		return &fleet.HostFileRetrieval{HostID: hostID, ExecutionID: execID, Status: fleet.HostFileRetrievalStatusCompleted}, nil
+	}
+	ds.GetHostFileRetrievalDataFunc = func(ctx context.Context, execID string) ([]byte, error) {
+		return []byte("content"), nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name             string
+		user             *fleet.User
+		shouldFailGlobal bool
+		shouldFailTeam   bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, true, true},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, true},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, true},
+		{"team admin, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, false},
+		{"team maintainer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, true, true},
+		{"team observer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, true},
+		{"team admin, DOES NOT belong to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			payload := fleet.HostFileRetrievalPayload{HostID: noTeamHostID, Path: "/var/log/system.log"}
+			_, err := svc.RequestHostFileRetrieval(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+			payload.HostID = team1HostID
+			_, err = svc.RequestHostFileRetrieval(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailTeam, err)
+
+			_, err = svc.ListHostFileRetrievals(ctx, noTeamHostID)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+			_, err = svc.ListHostFileRetrievals(ctx, team1HostID)
+			checkAuthErr(t, tt.shouldFailTeam, err)
+
+			_, _, err = svc.GetHostFileRetrieval(ctx, "no-team", true)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+			_, _, err = svc.GetHostFileRetrieval(ctx, "team1", true)
+			checkAuthErr(t, tt.shouldFailTeam, err)
+		})
+	}
+}
+
+func TestRequestHostFileRetrieval(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	host := &fleet.Host{ID: 1, Platform: "windows", Hostname: "win", OrbitNodeKey: ptr.String("abc"), TeamID: ptr.Uint(1)}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{FileRetrieval: fleet.FileRetrievalSettings{AllowedPaths: []string{"/"}}}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{FileRetrieval: fleet.FileRetrievalSettings{
+			AllowedPaths:  []string{`C:\ProgramData\App\Logs\`},
+			NotifyEndUser: true,
+		}}}, nil
+	}
+	var gotPayload *fleet.HostFileRetrievalPayload
+	ds.NewHostFileRetrievalFunc = func(ctx context.Context, payload *fleet.HostFileRetrievalPayload) (*fleet.HostFileRetrieval, error) {
+		gotPayload = payload
+		return &fleet.HostFileRetrieval{
+			HostID:        payload.HostID,
+			ExecutionID:   "x",
+			Path:          payload.Path,
+			NotifyEndUser: payload.NotifyEndUser,
+		}, nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	// the team's settings apply, not the global ones
+	_, err := svc.RequestHostFileRetrieval(ctx, &fleet.HostFileRetrievalPayload{HostID: 1, Path: `C:\Windows\System32\config\SAM`})
+	require.ErrorContains(t, err, fleet.HostFileRetrievalPathNotAllowedErrMsg)
+	require.False(t, ds.NewHostFileRetrievalFuncInvoked)
+
+	_, err = svc.RequestHostFileRetrieval(ctx, &fleet.HostFileRetrievalPayload{HostID: 1})
+	require.ErrorContains(t, err, "A path is required")
+
+	payload := &fleet.HostFileRetrievalPayload{HostID: 1, Path: `C:\ProgramData\App\Logs\app.log`}
+	fr, err := svc.RequestHostFileRetrieval(ctx, payload)
+	require.NoError(t, err)
+	require.Equal(t, "x", fr.ExecutionID)
+	require.True(t, fr.NotifyEndUser)
+	require.Equal(t, uint(1), *gotPayload.UserID)
+	require.Equal(t, fleet.ActivityTypeRequestedHostFileRetrieval{
+		HostID:          1,
+		HostDisplayName: "win",
+		ExecutionID:     "x",
+		Path:            `C:\ProgramData\App\Logs\app.log`,
+	}, activity)
+
+	// fleetd is required
+	host.OrbitNodeKey = nil
+	_, err = svc.RequestHostFileRetrieval(ctx, payload)
+	require.ErrorContains(t, err, fleet.HostFileRetrievalFleetdRequiredErrMsg)
+
+	// scripts must be enabled on the agent
+	host.OrbitNodeKey = ptr.String("abc")
+	host.ScriptsEnabled = ptr.Bool(false)
+	_, err = svc.RequestHostFileRetrieval(ctx, payload)
+	require.ErrorContains(t, err, fleet.HostFileRetrievalOrbitDisabledErrMsg)
+}
+
+func TestGetHostFileRetrievalDownloadActivity(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	status := fleet.HostFileRetrievalStatusPending
+	ds.GetHostFileRetrievalFunc = func(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error) {
+		return &fleet.HostFileRetrieval{HostID: 1, ExecutionID: execID, Path: "/etc/hosts", Status: status}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, Hostname: "linux"}, nil
+	}
+	ds.GetHostFileRetrievalDataFunc = func(ctx context.Context, execID string) ([]byte, error) {
+		return []byte("127.0.0.1 localhost"), nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activities = append(activities, act)
+		return nil
+	}
+
+	// getting the status does not create an activity
+	fr, data, err := svc.GetHostFileRetrieval(ctx, "x", false)
+	require.NoError(t, err)
+	require.Equal(t, fleet.HostFileRetrievalStatusPending, fr.Status)
+	require.Nil(t, data)
+	require.Empty(t, activities)
+
+	// cannot download before the file was retrieved
+	_, _, err = svc.GetHostFileRetrieval(ctx, "x", true)
+	require.ErrorContains(t, err, "The file was not retrieved from the host")
+	require.Empty(t, activities)
+
+	status = fleet.HostFileRetrievalStatusCompleted
+	_, data, err = svc.GetHostFileRetrieval(ctx, "x", true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("127.0.0.1 localhost"), data)
+	require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeDownloadedHostFile{
+		HostID:          1,
+		HostDisplayName: "linux",
+		ExecutionID:     "x",
+		Path:            "/etc/hosts",
+	}}, activities)
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create host group
+////////////////////////////////////////////////////////////////////////////////
+
+type createHostGroupRequest struct {
+	fleet.HostGroupPayload
+	// Hosts are the initial members of the group, they are optional.
+	fleet.HostGroupHostsPayload
+}
+
+type hostGroupResponse struct {
+	HostGroup *fleet.HostGroup `json:"host_group,omitempty"`
+	Err       error            `json:"error,omitempty"`
+}
+
+func (r hostGroupResponse) error() error { return r.Err }
+
+func createHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*createHostGroupRequest)
+	group, err := svc.NewHostGroup(ctx, req.HostGroupPayload, req.HostGroupHostsPayload)
+	if err != nil {
+		return hostGroupResponse{Err: err}, nil
+	}
+	return hostGroupResponse{HostGroup: group}, nil
+}
+
+func (svc *Service) NewHostGroup(ctx context.Context, payload fleet.HostGroupPayload, hosts fleet.HostGroupHostsPayload) (*fleet.HostGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if err := payload.Validate(true); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate host group")
+	}
+
+	var hostIDs []uint
+	if !hosts.Empty() {
+		var err error
+		if hostIDs, err = svc.hostGroupSelectedHostIDs(ctx, hosts); err != nil {
+			return nil, err
+		}
+	}
+
+	group := &fleet.HostGroup{Name: *payload.Name}
+	if payload.Description != nil {
+		group.Description = *payload.Description
+	}
+	if vc, ok := viewer.FromContext(ctx); ok {
+		group.AuthorID = ptr.Uint(vc.UserID())
+	}
+
+	group, err := svc.ds.NewHostGroup(ctx, group)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create host group")
+	}
+	if len(hostIDs) > 0 {
+		if err := svc.ds.AddHostsToHostGroup(ctx, group.ID, hostIDs); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "add hosts to new host group")
+		}
+		if group, err = svc.ds.HostGroup(ctx, svc.hostGroupTeamFilter(ctx), group.ID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get new host group")
+		}
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeCreatedHostGroup{
+		ID:   group.ID,
+		Name: group.Name,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for new host group")
+	}
+	return group, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host group
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostGroupRequest struct {
+	ID uint `url:"id"`
+}
+
+func getHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostGroupRequest)
+	group, err := svc.GetHostGroup(ctx, req.ID)
+	if err != nil {
+		return hostGroupResponse{Err: err}, nil
+	}
+	return hostGroupResponse{HostGroup: group}, nil
+}
+
+func (svc *Service) GetHostGroup(ctx context.Context, id uint) (*fleet.HostGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.HostGroup(ctx, svc.hostGroupTeamFilter(ctx), id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host groups
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostGroupsRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listHostGroupsResponse struct {
+	HostGroups []*fleet.HostGroup `json:"host_groups"`
+	Err        error              `json:"error,omitempty"`
+}
+
+func (r listHostGroupsResponse) error() error { return r.Err }
+
+func listHostGroupsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostGroupsRequest)
+	groups, err := svc.ListHostGroups(ctx, req.ListOptions)
+	if err != nil {
+		return listHostGroupsResponse{Err: err}, nil
+	}
+	if groups == nil {
+		groups = []*fleet.HostGroup{} // return empty json array instead of json null
+	}
+	return listHostGroupsResponse{HostGroups: groups}, nil
+}
+
+func (svc *Service) ListHostGroups(ctx context.Context, opt fleet.ListOptions) ([]*fleet.HostGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListHostGroups(ctx, svc.hostGroupTeamFilter(ctx), opt)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify host group
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyHostGroupRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.HostGroupPayload
+}
+
+func modifyHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyHostGroupRequest)
+	group, err := svc.ModifyHostGroup(ctx, req.ID, req.HostGroupPayload)
+	if err != nil {
+		return hostGroupResponse{Err: err}, nil
+	}
+	return hostGroupResponse{HostGroup: group}, nil
+}
+
+func (svc *Service) ModifyHostGroup(ctx context.Context, id uint, payload fleet.HostGroupPayload) (*fleet.HostGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if err := payload.Validate(false); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate host group")
+	}
+
+	group, err := svc.ds.HostGroup(ctx, svc.hostGroupTeamFilter(ctx), id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host group")
+	}
+	if payload.Name != nil {
+		group.Name = *payload.Name
+	}
+	if payload.Description != nil {
+		group.Description = *payload.Description
+	}
+
+	if _, err := svc.ds.SaveHostGroup(ctx, group); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save host group")
+	}
+	return svc.ds.HostGroup(ctx, svc.hostGroupTeamFilter(ctx), id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete host group
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteHostGroupRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteHostGroupResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteHostGroupResponse) error() error { return r.Err }
+
+func deleteHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteHostGroupRequest)
+	if err := svc.DeleteHostGroup(ctx, req.ID); err != nil {
+		return deleteHostGroupResponse{Err: err}, nil
+	}
+	return deleteHostGroupResponse{}, nil
+}
+
+func (svc *Service) DeleteHostGroup(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	group, err := svc.ds.HostGroup(ctx, svc.hostGroupTeamFilter(ctx), id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host group")
+	}
+	if err := svc.ds.DeleteHostGroup(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host group")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDeletedHostGroup{
+		ID:   group.ID,
+		Name: group.Name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for deleted host group")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Add/remove hosts of a host group
+////////////////////////////////////////////////////////////////////////////////
+
+type hostGroupHostsRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.HostGroupHostsPayload
+}
+
+func addHostsToHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*hostGroupHostsRequest)
+	group, err := svc.AddHostsToHostGroup(ctx, req.ID, req.HostGroupHostsPayload)
+	if err != nil {
+		return hostGroupResponse{Err: err}, nil
+	}
+	return hostGroupResponse{HostGroup: group}, nil
+}
+
+func (svc *Service) AddHostsToHostGroup(ctx context.Context, id uint, hosts fleet.HostGroupHostsPayload) (*fleet.HostGroup, error) {
+	return svc.updateHostGroupHosts(ctx, id, hosts, svc.ds.AddHostsToHostGroup)
+}
+
+func removeHostsFromHostGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*hostGroupHostsRequest)
+	group, err := svc.RemoveHostsFromHostGroup(ctx, req.ID, req.HostGroupHostsPayload)
+	if err != nil {
+		return hostGroupResponse{Err: err}, nil
+	}
+	return hostGroupResponse{HostGroup: group}, nil
+}
+
+func (svc *Service) RemoveHostsFromHostGroup(ctx context.Context, id uint, hosts fleet.HostGroupHostsPayload) (*fleet.HostGroup, error) {
+	return svc.updateHostGroupHosts(ctx, id, hosts, svc.ds.RemoveHostsFromHostGroup)
+}
+
+func (svc *Service) updateHostGroupHosts(ctx context.Context, id uint, hosts fleet.HostGroupHostsPayload,
+	update func(ctx context.Context, groupID uint, hostIDs []uint) error,
+) (*fleet.HostGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if hosts.Empty() {
+		return nil, fleet.NewInvalidArgumentError("host_ids", "One of host_ids, identifiers or filters is required.")
+	}
+
+	filter := svc.hostGroupTeamFilter(ctx)
+	if _, err := svc.ds.HostGroup(ctx, filter, id); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host group")
+	}
+
+	hostIDs, err := svc.hostGroupSelectedHostIDs(ctx, hosts)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(ctx, id, hostIDs); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update host group hosts")
+	}
+	return svc.ds.HostGroup(ctx, filter, id)
+}
+
+// hostGroupTeamFilter returns the team filter used to select the hosts of
+// host groups that the user can see.
+func (svc *Service) hostGroupTeamFilter(ctx context.Context) fleet.TeamFilter {
+	var user *fleet.User
+	if vc, ok := viewer.FromContext(ctx); ok {
+		user = vc.User
+	}
+	return fleet.TeamFilter{User: user, IncludeObserver: true}
+}
+
+// hostGroupSelectedHostIDs returns the ids of the existing hosts selected by
+// the payload that the user can see.
+func (svc *Service) hostGroupSelectedHostIDs(ctx context.Context, hosts fleet.HostGroupHostsPayload) ([]uint, error) {
+	filter := svc.hostGroupTeamFilter(ctx)
+
+	var hostIDs []uint
+	if len(hosts.HostIDs) > 0 {
+		ids, err := svc.ds.HostIDsInTargets(ctx, filter, fleet.HostTargets{HostIDs: hosts.HostIDs})
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get hosts by id")
+		}
+		hostIDs = append(hostIDs, ids...)
+	}
+
+	if len(hosts.Identifiers) > 0 {
+		identifiers := make([]string, 0, len(hosts.Identifiers))
+		for _, ident := range hosts.Identifiers {
+			if ident = strings.TrimSpace(ident); ident != "" {
+				identifiers = append(identifiers, ident)
+			}
+		}
+		ids, err := svc.ds.HostIDsByIdentifiers(ctx, filter, identifiers)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get hosts by identifier")
+		}
+		hostIDs = append(hostIDs, ids...)
+	}
+
+	if hosts.Filters != nil {
+		opt, lid, err := hostListOptionsFromFilters(hosts.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if opt == nil {
+			return nil, &fleet.BadRequestError{Message: "filters must be specified"}
+		}
+		ids, _, _, err := svc.hostIDsAndNamesFromFilters(ctx, *opt, lid)
+		if err != nil {
+			return nil, err
+		}
+		hostIDs = append(hostIDs, ids...)
+	}
+
+	return hostIDs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Run a script on the hosts of a host group
+////////////////////////////////////////////////////////////////////////////////
+
+type runHostGroupScriptRequest struct {
+	ID       uint `json:"-" url:"id"`
+	ScriptID uint `json:"script_id"`
+}
+
+type runHostGroupScriptResponse struct {
+	*fleet.HostGroupScriptRun
+	Err error `json:"error,omitempty"`
+}
+
+func (r runHostGroupScriptResponse) error() error { return r.Err }
+
+func runHostGroupScriptEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*runHostGroupScriptRequest)
+	run, err := svc.RunHostGroupScript(ctx, req.ID, req.ScriptID)
+	if err != nil {
+		return runHostGroupScriptResponse{Err: err}, nil
+	}
+	return runHostGroupScriptResponse{HostGroupScriptRun: run}, nil
+}
+
+func (svc *Service) RunHostGroupScript(ctx context.Context, id uint, scriptID uint) (*fleet.HostGroupScriptRun, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.HostGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	script, err := svc.ds.Script(ctx, scriptID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil, fleet.NewInvalidArgumentError("script_id", `No script exists for the provided "script_id".`).
+				WithStatus(http.StatusNotFound)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get script")
+	}
+	// the script can only run on hosts of its team, check upfront that the user
+	// can run it on those hosts.
+	if err := svc.authz.Authorize(ctx, &fleet.HostScriptResult{TeamID: script.TeamID, ScriptID: &script.ID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	filter := svc.hostGroupTeamFilter(ctx)
+	if _, err := svc.ds.HostGroup(ctx, filter, id); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host group")
+	}
+	hostIDs, err := svc.ds.HostIDsInTargets(ctx, filter, fleet.HostTargets{HostGroupIDs: []uint{id}})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host group hosts")
+	}
+	if len(hostIDs) > fleet.MaxHostGroupScriptRunHosts {
+		return nil, fleet.NewInvalidArgumentError("id",
+			fmt.Sprintf("A script can be run on at most %d hosts of a host group at once.", fleet.MaxHostGroupScriptRunHosts))
+	}
+
+	run := &fleet.HostGroupScriptRun{
+		HostGroupID: id,
+		ScriptID:    scriptID,
+		Executions:  []fleet.HostGroupScriptExecution{},
+		Skipped:     []fleet.HostGroupScriptSkippedHost{},
+	}
+	for _, hostID := range hostIDs {
+		res, err := svc.RunHostScript(ctx, &fleet.HostScriptRequestPayload{
+			HostID:   hostID,
+			ScriptID: ptr.Uint(scriptID),
+		}, 0)
+		if err != nil {
+			var forbidden *authz.Forbidden
+			if errors.As(err, &forbidden) {
+				return nil, err
+			}
+			run.Skipped = append(run.Skipped, fleet.HostGroupScriptSkippedHost{
+				HostID: hostID,
+				Error:  hostGroupScriptErrorMessage(err),
+			})
+			continue
+		}
+		run.Executions = append(run.Executions, fleet.HostGroupScriptExecution{
+			HostID:      hostID,
+			ExecutionID: res.ExecutionID,
+		})
+	}
+	return run, nil
+}
+
+// hostGroupScriptErrorMessage returns the message of an error returned when
+// running a script on a host, without the "validation failed" prefix of
+// invalid argument errors.
+func hostGroupScriptErrorMessage(err error) string {
+	var invalid interface{ Invalid() []map[string]string }
+	if errors.As(err, &invalid) {
+		if inv := invalid.Invalid(); len(inv) > 0 {
+			return inv[0]["reason"]
+		}
+	}
+	return err.Error()
+}
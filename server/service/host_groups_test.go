@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostGroupsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.NewHostGroupFunc = func(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
+		group.ID = 1
+		return group, nil
+	}
+	ds.HostGroupFunc = func(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
+		return &fleet.HostGroup{ID: id, Name: "g"}, nil
+	}
+	ds.ListHostGroupsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.HostGroup, error) {
+		return nil, nil
+	}
+	ds.SaveHostGroupFunc = func(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
+		return group, nil
+	}
+	ds.DeleteHostGroupFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return targets.HostIDs, nil
+	}
+	ds.AddHostsToHostGroupFunc = func(ctx context.Context, groupID uint, hostIDs []uint) error {
+		return nil
+	}
+	ds.RemoveHostsFromHostGroupFunc = func(ctx context.Context, groupID uint, hostIDs []uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailRead  bool
+		shouldFailWrite bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, false, true},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, true},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false, true},
+		{"team observer", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, false, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewHostGroup(ctx, fleet.HostGroupPayload{Name: ptr.String("g")}, fleet.HostGroupHostsPayload{})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+			_, err = svc.ModifyHostGroup(ctx, 1, fleet.HostGroupPayload{Name: ptr.String("g2")})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+			_, err = svc.AddHostsToHostGroup(ctx, 1, fleet.HostGroupHostsPayload{HostIDs: []uint{1}})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+			_, err = svc.RemoveHostsFromHostGroup(ctx, 1, fleet.HostGroupHostsPayload{HostIDs: []uint{1}})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+			err = svc.DeleteHostGroup(ctx, 1)
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.GetHostGroup(ctx, 1)
+			checkAuthErr(t, tt.shouldFailRead, err)
+			_, err = svc.ListHostGroups(ctx, fleet.ListOptions{})
+			checkAuthErr(t, tt.shouldFailRead, err)
+		})
+	}
+}
+
+func TestNewHostGroup(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	var created *fleet.HostGroup
+	ds.NewHostGroupFunc = func(ctx context.Context, group *fleet.HostGroup) (*fleet.HostGroup, error) {
+		created = group
+		group.ID = 1
+		return group, nil
+	}
+	ds.HostGroupFunc = func(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
+		return &fleet.HostGroup{ID: id, Name: created.Name, HostCount: 3}, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		// host 99 does not exist
+		return []uint{1}, nil
+	}
+	ds.HostIDsByIdentifiersFunc = func(ctx context.Context, filter fleet.TeamFilter, identifiers []string) ([]uint, error) {
+		require.Equal(t, []string{"host-2", "ABC123"}, identifiers)
+		return []uint{2, 3}, nil
+	}
+	var addedHostIDs []uint
+	ds.AddHostsToHostGroupFunc = func(ctx context.Context, groupID uint, hostIDs []uint) error {
+		addedHostIDs = hostIDs
+		return nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	_, err := svc.NewHostGroup(ctx, fleet.HostGroupPayload{Name: ptr.String(" ")}, fleet.HostGroupHostsPayload{})
+	require.ErrorContains(t, err, "The name cannot be empty")
+	require.False(t, ds.NewHostGroupFuncInvoked)
+
+	group, err := svc.NewHostGroup(ctx, fleet.HostGroupPayload{Name: ptr.String(" Incident 42 ")}, fleet.HostGroupHostsPayload{
+		HostIDs:     []uint{1, 99},
+		Identifiers: []string{"host-2", " ABC123", ""},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Incident 42", created.Name)
+	require.Equal(t, uint(1), *created.AuthorID)
+	require.Equal(t, []uint{1, 2, 3}, addedHostIDs)
+	require.Equal(t, uint(3), group.HostCount)
+	require.Equal(t, fleet.ActivityTypeCreatedHostGroup{ID: 1, Name: "Incident 42"}, activity)
+
+	// hosts are required to update the members
+	_, err = svc.AddHostsToHostGroup(ctx, 1, fleet.HostGroupHostsPayload{})
+	require.ErrorContains(t, err, "One of host_ids, identifiers or filters is required")
+}
+
+func TestRunHostGroupScript(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, Hostname: "h1", OrbitNodeKey: ptr.String("a")},
+		2: {ID: 2, Hostname: "h2"}, // no fleetd
+		3: {ID: 3, Hostname: "h3", OrbitNodeKey: ptr.String("c"), TeamID: ptr.Uint(1)},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.ScriptFunc = func(ctx context.Context, id uint) (*fleet.Script, error) {
+		if id != 1 {
+			return nil, &notFoundError{}
+		}
+		return &fleet.Script{ID: id, Name: "script.sh"}, nil
+	}
+	ds.GetScriptContentsFunc = func(ctx context.Context, id uint) ([]byte, error) {
+		return []byte("echo"), nil
+	}
+	ds.HostGroupFunc = func(ctx context.Context, filter fleet.TeamFilter, id uint) (*fleet.HostGroup, error) {
+		return &fleet.HostGroup{ID: id}, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		require.Equal(t, []uint{5}, targets.HostGroupIDs)
+		return []uint{1, 2, 3}, nil
+	}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.IsExecutionPendingForHostFunc = func(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+		return nil, nil
+	}
+	ds.NewHostScriptExecutionRequestFunc = func(ctx context.Context, request *fleet.HostScriptRequestPayload) (*fleet.HostScriptResult, error) {
+		return &fleet.HostScriptResult{HostID: request.HostID, ExecutionID: "exec"}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	_, err := svc.RunHostGroupScript(ctx, 5, 2)
+	require.ErrorContains(t, err, `No script exists for the provided "script_id"`)
+
+	run, err := svc.RunHostGroupScript(ctx, 5, 1)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.HostGroupScriptExecution{{HostID: 1, ExecutionID: "exec"}}, run.Executions)
+	require.Len(t, run.Skipped, 2)
+	require.Equal(t, uint(2), run.Skipped[0].HostID)
+	require.Contains(t, run.Skipped[0].Error, fleet.RunScriptDisabledErrMsg)
+	require.Equal(t, uint(3), run.Skipped[1].HostID)
+	require.Equal(t, "The script does not belong to the same team (or no team) as the host.", run.Skipped[1].Error)
+
+	// team users cannot run a script of another team (no team here)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}})
+	_, err = svc.RunHostGroupScript(ctx, 5, 1)
+	checkAuthErr(t, true, err)
+}
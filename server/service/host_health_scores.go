@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// UpdateHostHealthScores recalculates the health score of all hosts from
+// their failing policies, exploitable vulnerabilities, agent health and MDM
+// status. It is meant to be called by a cron job, the hosts are processed in
+// batches of fleet.HostHealthScoreBatchSize.
+func UpdateHostHealthScores(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	// the MDM status only affects the score of the hosts that can be managed
+	// by Fleet's MDM.
+	mdmEnabled := func(platform string) bool {
+		switch platform {
+		case "darwin", "ios", "ipados":
+			return appConfig.MDM.EnabledAndConfigured
+		case "windows":
+			return appConfig.MDM.WindowsEnabledAndConfigured
+		default:
+			return false
+		}
+	}
+
+	now := time.Now()
+	var afterHostID uint
+	var count int
+	for {
+		inputs, err := ds.ListHostHealthScoreInputs(ctx, afterHostID, now.Add(-fleet.HostHealthScoreRecoveryWindow), fleet.HostHealthScoreBatchSize)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "list host health score inputs")
+		}
+		if len(inputs) == 0 {
+			break
+		}
+
+		scores := make([]*fleet.HostHealthScore, 0, len(inputs))
+		for _, in := range inputs {
+			hs := fleet.ComputeHostHealthScore(*in, now, mdmEnabled)
+			scores = append(scores, &hs)
+		}
+		if err := ds.UpsertHostHealthScores(ctx, scores); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert host health scores")
+		}
+
+		count += len(inputs)
+		afterHostID = inputs[len(inputs)-1].HostID
+		if len(inputs) < fleet.HostHealthScoreBatchSize {
+			break
+		}
+	}
+	level.Debug(logger).Log("msg", "updated host health scores", "count", count)
+	return nil
+}
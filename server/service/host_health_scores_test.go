@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateHostHealthScores(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
+	}
+
+	// two full batches and a partial one
+	hostCount := 2*fleet.HostHealthScoreBatchSize + 10
+	var afterIDs []uint
+	ds.ListHostHealthScoreInputsFunc = func(ctx context.Context, afterHostID uint, recoveredSince time.Time, limit int) ([]*fleet.HostHealthScoreInputs, error) {
+		require.Equal(t, fleet.HostHealthScoreBatchSize, limit)
+		require.WithinDuration(t, time.Now().Add(-fleet.HostHealthScoreRecoveryWindow), recoveredSince, time.Minute)
+		afterIDs = append(afterIDs, afterHostID)
+
+		var inputs []*fleet.HostHealthScoreInputs
+		for id := afterHostID + 1; id <= uint(hostCount) && len(inputs) < limit; id++ {
+			in := &fleet.HostHealthScoreInputs{HostID: id, Platform: "windows", SeenTime: time.Now()}
+			if id == 1 {
+				in.FailingPolicies = 1
+				in.MDMEnrolled = ptr.Bool(false)
+			}
+			if id == 2 {
+				// the MDM status of macOS hosts is ignored, MDM is not enabled
+				in.Platform = "darwin"
+				in.MDMEnrolled = ptr.Bool(false)
+			}
+			inputs = append(inputs, in)
+		}
+		return inputs, nil
+	}
+	upserted := make(map[uint]*fleet.HostHealthScore)
+	ds.UpsertHostHealthScoresFunc = func(ctx context.Context, scores []*fleet.HostHealthScore) error {
+		for _, s := range scores {
+			upserted[s.HostID] = s
+		}
+		return nil
+	}
+
+	require.NoError(t, UpdateHostHealthScores(ctx, ds, kitlog.NewNopLogger()))
+	require.Equal(t, []uint{0, uint(fleet.HostHealthScoreBatchSize), uint(2 * fleet.HostHealthScoreBatchSize)}, afterIDs)
+	require.Len(t, upserted, hostCount)
+	require.Equal(t, &fleet.HostHealthScore{HostID: 1, Score: 85, FailingPoliciesPenalty: 5, MDMPenalty: 10}, upserted[1])
+	require.Equal(t, &fleet.HostHealthScore{HostID: 2, Score: 100}, upserted[2])
+	require.Equal(t, 100, upserted[uint(hostCount)].Score)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Request host log collection
+////////////////////////////////////////////////////////////////////////////////
+
+type requestHostLogCollectionRequest struct {
+	HostID uint `url:"id"`
+	fleet.HostLogCollectionPayload
+}
+
+type requestHostLogCollectionResponse struct {
+	LogCollection *fleet.HostLogCollection `json:"log_collection,omitempty"`
+	Err           error                    `json:"error,omitempty"`
+}
+
+func (r requestHostLogCollectionResponse) error() error { return r.Err }
+func (r requestHostLogCollectionResponse) Status() int  { return http.StatusAccepted }
+
+func requestHostLogCollectionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*requestHostLogCollectionRequest)
+	req.HostLogCollectionPayload.HostID = req.HostID
+	lc, err := svc.RequestHostLogCollection(ctx, &req.HostLogCollectionPayload)
+	if err != nil {
+		return requestHostLogCollectionResponse{Err: err}, nil
+	}
+	return requestHostLogCollectionResponse{LogCollection: lc}, nil
+}
+
+func (svc *Service) RequestHostLogCollection(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error) {
+	// must load the host to get the team to authorize with the proper team id
+	// and to validate the type of logs for the host's platform.
+	host, err := svc.ds.Host(ctx, payload.HostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had access to collect logs (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostLogCollection{}, fleet.ActionWrite); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostLogCollection{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the logs are collected by fleetd, using the same capability as scripts
+	// as it executes commands on the host.
+	if host.OrbitNodeKey == nil || *host.OrbitNodeKey == "" {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostLogCollectionFleetdRequiredErrMsg), http.StatusUnprocessableEntity)
+	}
+	if host.ScriptsEnabled != nil && !*host.ScriptsEnabled {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostLogCollectionOrbitDisabledErrMsg), http.StatusUnprocessableEntity)
+	}
+
+	if payload.EndTime.IsZero() {
+		payload.EndTime = time.Now().UTC()
+	}
+	if err := payload.Validate(host.FleetPlatform()); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate log collection")
+	}
+
+	if user := authz.UserFromContext(ctx); user != nil {
+		payload.UserID = &user.ID
+	}
+	lc, err := svc.ds.NewHostLogCollection(ctx, payload)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create host log collection")
+	}
+	lc.TeamID = host.TeamID
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeRequestedHostLogCollection{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		ExecutionID:     lc.ExecutionID,
+		LogType:         lc.Type,
+		StartTime:       lc.StartTime,
+		EndTime:         lc.EndTime,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for host log collection")
+	}
+	return lc, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host log collections
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostLogCollectionsRequest struct {
+	HostID uint `url:"id"`
+}
+
+type listHostLogCollectionsResponse struct {
+	LogCollections []*fleet.HostLogCollection `json:"log_collections"`
+	Err            error                      `json:"error,omitempty"`
+}
+
+func (r listHostLogCollectionsResponse) error() error { return r.Err }
+
+func listHostLogCollectionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostLogCollectionsRequest)
+	lcs, err := svc.ListHostLogCollections(ctx, req.HostID)
+	if err != nil {
+		return listHostLogCollectionsResponse{Err: err}, nil
+	}
+	if lcs == nil {
+		lcs = []*fleet.HostLogCollection{} // return empty json array instead of json null
+	}
+	return listHostLogCollectionsResponse{LogCollections: lcs}, nil
+}
+
+func (svc *Service) ListHostLogCollections(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had global access (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostLogCollection{}, fleet.ActionRead); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostLogCollection{TeamID: host.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	lcs, err := svc.ds.ListHostLogCollections(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host log collections")
+	}
+	for _, lc := range lcs {
+		lc.TeamID = host.TeamID
+	}
+	return lcs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host log collection
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostLogCollectionRequest struct {
+	ExecutionID string `url:"execution_id"`
+	Alt         string `query:"alt,optional"`
+}
+
+type getHostLogCollectionResponse struct {
+	*fleet.HostLogCollection
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostLogCollectionResponse) error() error { return r.Err }
+
+func getHostLogCollectionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostLogCollectionRequest)
+
+	downloadRequested := req.Alt == "media"
+	lc, data, err := svc.GetHostLogCollection(ctx, req.ExecutionID, downloadRequested)
+	if err != nil {
+		return getHostLogCollectionResponse{Err: err}, nil
+	}
+
+	if downloadRequested {
+		return downloadFileResponse{
+			content:     data,
+			filename:    lc.Filename(),
+			contentType: "application/gzip",
+		}, nil
+	}
+	return getHostLogCollectionResponse{HostLogCollection: lc}, nil
+}
+
+func (svc *Service) GetHostLogCollection(ctx context.Context, execID string, downloadRequested bool) (*fleet.HostLogCollection, []byte, error) {
+	lc, err := svc.ds.GetHostLogCollection(ctx, execID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostLogCollection{}, fleet.ActionRead); err != nil {
+				return nil, nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host log collection")
+	}
+
+	// authorize with the team of the host, if the host was deleted since the
+	// request, only global users can access the logs.
+	host, err := svc.ds.HostLite(ctx, lc.HostID)
+	if err != nil && !fleet.IsNotFound(err) {
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if host != nil {
+		lc.TeamID = host.TeamID
+	}
+	if err := svc.authz.Authorize(ctx, lc, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	if downloadRequested {
+		if lc.Status != fleet.HostLogCollectionStatusCompleted {
+			return nil, nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+				Message: "Couldn't download logs. The logs were not collected from the host.",
+			})
+		}
+		data, err = svc.ds.GetHostLogCollectionData(ctx, execID)
+		if err != nil {
+			return nil, nil, ctxerr.Wrap(ctx, err, "get host log collection data")
+		}
+	}
+	return lc, data, nil
+}
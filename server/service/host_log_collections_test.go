@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLogCollectionsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		noTeamHostID = 1
+		team1HostID  = 2
+	)
+	hosts := map[uint]*fleet.Host{
+		noTeamHostID: {ID: noTeamHostID, Platform: "darwin", OrbitNodeKey: ptr.String("abc")},
+		team1HostID:  {ID: team1HostID, Platform: "darwin", OrbitNodeKey: ptr.String("def"), TeamID: ptr.Uint(1)},
+	}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.NewHostLogCollectionFunc = func(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error) {
+		return &fleet.HostLogCollection{HostID: payload.HostID, ExecutionID: "x", Type: payload.Type}, nil
+	}
+	ds.ListHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+		return nil, nil
+	}
+	ds.GetHostLogCollectionFunc = func(ctx context.Context, execID string) (*fleet.HostLogCollection, error) {
+		hostID := uint(noTeamHostID)
+		if execID == "team1" {
+			hostID = team1HostID
+		}
+		return &fleet.HostLogCollection{HostID: hostID, ExecutionID: execID, Status: fleet.HostLogCollectionStatusCompleted}, nil
+	}
+	ds.GetHostLogCollectionDataFunc = func(ctx context.Context, execID string) ([]byte, error) {
+		return []byte("logs"), nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalRead  bool
+		shouldFailGlobalWrite bool
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, true, true, true},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, true, true, true},
+		{"team admin, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, true, false, false},
+		{"team maintainer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, true, true, false, false},
+		{"team observer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, true, true, true},
+		{"team admin, DOES NOT belong to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, true, true, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			payload := fleet.HostLogCollectionPayload{
+				HostID:    noTeamHostID,
+				Type:      fleet.HostLogCollectionMacOSUnifiedLog,
+				Predicate: `process == "sshd"`,
+				StartTime: time.Now().Add(-time.Hour),
+			}
+			_, err := svc.RequestHostLogCollection(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			payload.HostID = team1HostID
+			_, err = svc.RequestHostLogCollection(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ListHostLogCollections(ctx, noTeamHostID)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.ListHostLogCollections(ctx, team1HostID)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, _, err = svc.GetHostLogCollection(ctx, "no-team", true)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, _, err = svc.GetHostLogCollection(ctx, "team1", true)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+}
+
+func TestRequestHostLogCollection(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	host := &fleet.Host{ID: 1, Platform: "windows", Hostname: "win", OrbitNodeKey: ptr.String("abc")}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	var gotPayload *fleet.HostLogCollectionPayload
+	ds.NewHostLogCollectionFunc = func(ctx context.Context, payload *fleet.HostLogCollectionPayload) (*fleet.HostLogCollection, error) {
+		gotPayload = payload
+		return &fleet.HostLogCollection{
+			HostID:      payload.HostID,
+			ExecutionID: "x",
+			Type:        payload.Type,
+			StartTime:   payload.StartTime,
+			EndTime:     payload.EndTime,
+		}, nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	start := time.Now().Add(-time.Hour)
+	payload := &fleet.HostLogCollectionPayload{
+		HostID:    1,
+		Type:      fleet.HostLogCollectionMacOSUnifiedLog,
+		Predicate: "true",
+		StartTime: start,
+	}
+	_, err := svc.RequestHostLogCollection(ctx, payload)
+	require.ErrorContains(t, err, "Unified logs can only be collected from macOS hosts")
+	require.False(t, ds.NewHostLogCollectionFuncInvoked)
+
+	payload = &fleet.HostLogCollectionPayload{
+		HostID:    1,
+		Type:      fleet.HostLogCollectionWindowsEventLog,
+		Channels:  []string{"System"},
+		StartTime: start,
+	}
+	lc, err := svc.RequestHostLogCollection(ctx, payload)
+	require.NoError(t, err)
+	require.Equal(t, "x", lc.ExecutionID)
+	require.Equal(t, uint(1), *gotPayload.UserID)
+	require.False(t, gotPayload.EndTime.IsZero()) // defaults to now
+	require.Equal(t, fleet.ActivityTypeRequestedHostLogCollection{
+		HostID:          1,
+		HostDisplayName: "win",
+		ExecutionID:     "x",
+		LogType:         fleet.HostLogCollectionWindowsEventLog,
+		StartTime:       gotPayload.StartTime,
+		EndTime:         gotPayload.EndTime,
+	}, activity)
+
+	// fleetd is required
+	host.OrbitNodeKey = nil
+	_, err = svc.RequestHostLogCollection(ctx, payload)
+	require.ErrorContains(t, err, fleet.HostLogCollectionFleetdRequiredErrMsg)
+
+	// scripts must be enabled on the agent
+	host.OrbitNodeKey = ptr.String("abc")
+	host.ScriptsEnabled = ptr.Bool(false)
+	_, err = svc.RequestHostLogCollection(ctx, payload)
+	require.ErrorContains(t, err, fleet.HostLogCollectionOrbitDisabledErrMsg)
+}
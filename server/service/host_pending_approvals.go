@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List hosts pending enrollment approval
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostsPendingApprovalResponse struct {
+	Hosts []*fleet.HostPendingApproval `json:"hosts"`
+	Err   error                        `json:"error,omitempty"`
+}
+
+func (r listHostsPendingApprovalResponse) error() error { return r.Err }
+
+func listHostsPendingApprovalEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	hosts, err := svc.ListHostsPendingApproval(ctx)
+	if err != nil {
+		return listHostsPendingApprovalResponse{Err: err}, nil
+	}
+	return listHostsPendingApprovalResponse{Hosts: hosts}, nil
+}
+
+func (svc *Service) ListHostsPendingApproval(ctx context.Context) ([]*fleet.HostPendingApproval, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	return svc.ds.ListHostsPendingApproval(ctx, filter)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Approve a host enrollment
+////////////////////////////////////////////////////////////////////////////////
+
+type approveHostEnrollmentRequest struct {
+	HostID uint `url:"id"`
+}
+
+type approveHostEnrollmentResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r approveHostEnrollmentResponse) error() error { return r.Err }
+func (r approveHostEnrollmentResponse) Status() int  { return http.StatusNoContent }
+
+func approveHostEnrollmentEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*approveHostEnrollmentRequest)
+	if err := svc.ApproveHostEnrollment(ctx, req.HostID); err != nil {
+		return approveHostEnrollmentResponse{Err: err}, nil
+	}
+	return approveHostEnrollmentResponse{}, nil
+}
+
+func (svc *Service) ApproveHostEnrollment(ctx context.Context, hostID uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host for enrollment approval")
+	}
+
+	// authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if err := svc.ds.ApproveHostEnrollment(ctx, host.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "approve host enrollment")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeApprovedHostEnrollment{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		HostSerial:      host.HardwareSerial,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for approved host enrollment")
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WatchBeam/clock"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query/live_query_mock"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPendingApprovalAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		teamHostID   = 1
+		globalHostID = 2
+	)
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		if id == teamHostID {
+			return &fleet.Host{ID: id, TeamID: ptr.Uint(1), HardwareSerial: "serial1"}, nil
+		}
+		return &fleet.Host{ID: id, HardwareSerial: "serial2"}, nil
+	}
+	ds.ListHostsPendingApprovalFunc = func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostPendingApproval, error) {
+		return nil, nil
+	}
+	ds.ApproveHostEnrollmentFunc = func(ctx context.Context, hostID uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailList        bool
+		shouldFailTeamWrite   bool
+		shouldFailGlobalWrite bool
+	}{
+		{
+			name:                  "global admin",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+		},
+		{
+			name:                  "global maintainer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+		},
+		{
+			name:                  "global observer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team observer, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, DOES NOT belong to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailList:        false,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListHostsPendingApproval(ctx)
+			checkAuthErr(t, tt.shouldFailList, err)
+			err = svc.ApproveHostEnrollment(ctx, teamHostID)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			err = svc.ApproveHostEnrollment(ctx, globalHostID)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+		})
+	}
+}
+
+func TestHostPendingApprovalGating(t *testing.T) {
+	host := &fleet.Host{ID: 1, Platform: "darwin", OsqueryHostID: ptr.String("test"), PendingApproval: true}
+
+	t.Run("policies", func(t *testing.T) {
+		ds := new(mock.Store)
+		lq := live_query_mock.New(t)
+		svc, ctx := newTestServiceWithClock(t, ds, nil, lq, clock.NewMockClock())
+
+		ds.LabelQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+			return map[string]string{}, nil
+		}
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{}, nil
+		}
+		ds.PolicyQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+			return map[string]string{"1": "SELECT 1"}, nil
+		}
+		lq.On("QueriesForHost", host.ID).Return(map[string]string{}, nil)
+
+		queries, _, _, err := svc.GetDistributedQueries(hostctx.NewContext(ctx, host))
+		require.NoError(t, err)
+		require.False(t, ds.PolicyQueriesForHostFuncInvoked)
+		for name := range queries {
+			require.NotContains(t, name, hostPolicyQueryPrefix)
+		}
+		require.NotContains(t, queries, hostNoPoliciesWildcard)
+	})
+
+	t.Run("scripts", func(t *testing.T) {
+		ds := new(mock.Store)
+		svc, ctx := newTestService(t, ds, nil, nil)
+
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{}, nil
+		}
+		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+			return []*fleet.HostScriptResult{{HostID: hostID, ExecutionID: "abc"}}, nil
+		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
+		ds.GetHostScriptExecutionResultFunc = func(ctx context.Context, executionID string) (*fleet.HostScriptResult, error) {
+			return &fleet.HostScriptResult{HostID: host.ID, ExecutionID: executionID, ScriptContents: "echo"}, nil
+		}
+
+		ctx = test.HostContext(ctx, host)
+		cfg, err := svc.GetOrbitConfig(ctx)
+		require.NoError(t, err)
+		require.Empty(t, cfg.Notifications.PendingScriptExecutionIDs)
+		require.False(t, ds.ListPendingHostScriptExecutionsFuncInvoked)
+
+		_, err = svc.GetHostScript(ctx, "abc")
+		require.Error(t, err)
+		var nfe fleet.NotFoundError
+		require.ErrorAs(t, err, &nfe)
+
+		// once approved, the host receives its scripts
+		approved := *host
+		approved.PendingApproval = false
+		ctx = test.HostContext(ctx, &approved)
+		cfg, err = svc.GetOrbitConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"abc"}, cfg.Notifications.PendingScriptExecutionIDs)
+		_, err = svc.GetHostScript(ctx, "abc")
+		require.NoError(t, err)
+	})
+}
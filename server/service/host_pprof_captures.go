@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Request host pprof capture
+////////////////////////////////////////////////////////////////////////////////
+
+type requestHostPprofCaptureRequest struct {
+	HostID uint `url:"id"`
+	fleet.HostPprofCapturePayload
+}
+
+type requestHostPprofCaptureResponse struct {
+	PprofCapture *fleet.HostPprofCapture `json:"pprof_capture,omitempty"`
+	Err          error                   `json:"error,omitempty"`
+}
+
+func (r requestHostPprofCaptureResponse) error() error { return r.Err }
+func (r requestHostPprofCaptureResponse) Status() int  { return http.StatusAccepted }
+
+func requestHostPprofCaptureEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*requestHostPprofCaptureRequest)
+	req.HostPprofCapturePayload.HostID = req.HostID
+	pc, err := svc.RequestHostPprofCapture(ctx, &req.HostPprofCapturePayload)
+	if err != nil {
+		return requestHostPprofCaptureResponse{Err: err}, nil
+	}
+	return requestHostPprofCaptureResponse{PprofCapture: pc}, nil
+}
+
+func (svc *Service) RequestHostPprofCapture(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error) {
+	// must load the host to get the team to authorize with the proper team id.
+	host, err := svc.ds.HostLite(ctx, payload.HostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had access to capture profiles (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostPprofCapture{}, fleet.ActionWrite); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostPprofCapture{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the profile is of the fleetd agent itself, so unlike log collection or
+	// file retrieval it does not require scripts to be enabled.
+	if host.OrbitNodeKey == nil || *host.OrbitNodeKey == "" {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostPprofCaptureFleetdRequiredErrMsg), http.StatusUnprocessableEntity)
+	}
+	if err := payload.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate pprof capture")
+	}
+
+	if user := authz.UserFromContext(ctx); user != nil {
+		payload.UserID = &user.ID
+	}
+	pc, err := svc.ds.NewHostPprofCapture(ctx, payload)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create host pprof capture")
+	}
+	pc.TeamID = host.TeamID
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeRequestedHostPprofCapture{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		ExecutionID:     pc.ExecutionID,
+		ProfileType:     pc.Type,
+		DurationSeconds: pc.DurationSeconds,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for host pprof capture")
+	}
+	return pc, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host pprof captures
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostPprofCapturesRequest struct {
+	HostID uint `url:"id"`
+}
+
+type listHostPprofCapturesResponse struct {
+	PprofCaptures []*fleet.HostPprofCapture `json:"pprof_captures"`
+	Err           error                     `json:"error,omitempty"`
+}
+
+func (r listHostPprofCapturesResponse) error() error { return r.Err }
+
+func listHostPprofCapturesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostPprofCapturesRequest)
+	pcs, err := svc.ListHostPprofCaptures(ctx, req.HostID)
+	if err != nil {
+		return listHostPprofCapturesResponse{Err: err}, nil
+	}
+	if pcs == nil {
+		pcs = []*fleet.HostPprofCapture{} // return empty json array instead of json null
+	}
+	return listHostPprofCapturesResponse{PprofCaptures: pcs}, nil
+}
+
+func (svc *Service) ListHostPprofCaptures(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had global access (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostPprofCapture{}, fleet.ActionRead); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostPprofCapture{TeamID: host.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	pcs, err := svc.ds.ListHostPprofCaptures(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host pprof captures")
+	}
+	for _, pc := range pcs {
+		pc.TeamID = host.TeamID
+	}
+	return pcs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host pprof capture
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostPprofCaptureRequest struct {
+	ExecutionID string `url:"execution_id"`
+	Alt         string `query:"alt,optional"`
+}
+
+type getHostPprofCaptureResponse struct {
+	*fleet.HostPprofCapture
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostPprofCaptureResponse) error() error { return r.Err }
+
+func getHostPprofCaptureEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostPprofCaptureRequest)
+
+	downloadRequested := req.Alt == "media"
+	pc, data, err := svc.GetHostPprofCapture(ctx, req.ExecutionID, downloadRequested)
+	if err != nil {
+		return getHostPprofCaptureResponse{Err: err}, nil
+	}
+
+	if downloadRequested {
+		return downloadFileResponse{
+			content:     data,
+			filename:    pc.Filename(),
+			contentType: "application/octet-stream",
+		}, nil
+	}
+	return getHostPprofCaptureResponse{HostPprofCapture: pc}, nil
+}
+
+func (svc *Service) GetHostPprofCapture(ctx context.Context, execID string, downloadRequested bool) (*fleet.HostPprofCapture, []byte, error) {
+	pc, err := svc.ds.GetHostPprofCapture(ctx, execID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostPprofCapture{}, fleet.ActionRead); err != nil {
+				return nil, nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host pprof capture")
+	}
+
+	// authorize with the team of the host, if the host was deleted since the
+	// request, only global users can access the profile.
+	host, err := svc.ds.HostLite(ctx, pc.HostID)
+	if err != nil && !fleet.IsNotFound(err) {
+		svc.authz.SkipAuthorization(ctx)
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if host != nil {
+		pc.TeamID = host.TeamID
+	}
+	if err := svc.authz.Authorize(ctx, pc, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+
+	if !downloadRequested {
+		return pc, nil, nil
+	}
+
+	if pc.Status != fleet.HostPprofCaptureStatusCompleted {
+		return nil, nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't download profile. The profile was not captured on the host.",
+		})
+	}
+	data, err := svc.ds.GetHostPprofCaptureData(ctx, execID)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "get host pprof capture data")
+	}
+	return pc, data, nil
+}
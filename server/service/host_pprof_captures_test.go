@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPprofCapturesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		noTeamHostID = 1
+		team1HostID  = 2
+	)
+	hosts := map[uint]*fleet.Host{
+		noTeamHostID: {ID: noTeamHostID, Platform: "darwin", OrbitNodeKey: ptr.String("abc")},
+		team1HostID:  {ID: team1HostID, Platform: "darwin", OrbitNodeKey: ptr.String("def"), TeamID: ptr.Uint(1)},
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.NewHostPprofCaptureFunc = func(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error) {
+		return &fleet.HostPprofCapture{HostID: payload.HostID, ExecutionID: "x", Type: payload.Type}, nil
+	}
+	ds.ListHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+		return nil, nil
+	}
+	ds.GetHostPprofCaptureFunc = func(ctx context.Context, execID string) (*fleet.HostPprofCapture, error) {
+		hostID := uint(noTeamHostID)
+		if execID == "team1" {
+			hostID = team1HostID
+		}
+		return &fleet.HostPprofCapture{HostID: hostID, ExecutionID: execID, Status: fleet.HostPprofCaptureStatusCompleted}, nil
+	}
+	ds.GetHostPprofCaptureDataFunc = func(ctx context.Context, execID string) ([]byte, error) {
+		return []byte("profile"), nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalRead  bool
+		shouldFailGlobalWrite bool
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, true, true, true},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, true, true, true},
+		{"team admin, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, true, false, false},
+		{"team maintainer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, true, true, false, false},
+		{"team observer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, true, true, true},
+		{"team admin, DOES NOT belong to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, true, true, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			payload := fleet.HostPprofCapturePayload{HostID: noTeamHostID, Type: fleet.HostPprofProfileHeap}
+			_, err := svc.RequestHostPprofCapture(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			payload.HostID = team1HostID
+			_, err = svc.RequestHostPprofCapture(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ListHostPprofCaptures(ctx, noTeamHostID)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.ListHostPprofCaptures(ctx, team1HostID)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, _, err = svc.GetHostPprofCapture(ctx, "no-team", true)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, _, err = svc.GetHostPprofCapture(ctx, "team1", true)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+}
+
+func TestRequestHostPprofCapture(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	host := &fleet.Host{ID: 1, Platform: "ubuntu", Hostname: "linux", OrbitNodeKey: ptr.String("abc"), ScriptsEnabled: ptr.Bool(false)}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	var gotPayload *fleet.HostPprofCapturePayload
+	ds.NewHostPprofCaptureFunc = func(ctx context.Context, payload *fleet.HostPprofCapturePayload) (*fleet.HostPprofCapture, error) {
+		gotPayload = payload
+		return &fleet.HostPprofCapture{
+			HostID:          payload.HostID,
+			ExecutionID:     "x",
+			Type:            payload.Type,
+			DurationSeconds: payload.DurationSeconds,
+		}, nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	_, err := svc.RequestHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{HostID: 1, Type: "block"})
+	require.ErrorContains(t, err, "Invalid profile type")
+	_, err = svc.RequestHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{HostID: 1, Type: fleet.HostPprofProfileCPU, DurationSeconds: 600})
+	require.ErrorContains(t, err, "cannot be longer than 120 seconds")
+	require.False(t, ds.NewHostPprofCaptureFuncInvoked)
+
+	// scripts do not need to be enabled on the agent
+	pc, err := svc.RequestHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{HostID: 1, Type: fleet.HostPprofProfileCPU})
+	require.NoError(t, err)
+	require.Equal(t, "x", pc.ExecutionID)
+	require.Equal(t, uint(1), *gotPayload.UserID)
+	require.Equal(t, uint(30), gotPayload.DurationSeconds) // default duration
+	require.Equal(t, fleet.ActivityTypeRequestedHostPprofCapture{
+		HostID:          1,
+		HostDisplayName: "linux",
+		ExecutionID:     "x",
+		ProfileType:     fleet.HostPprofProfileCPU,
+		DurationSeconds: 30,
+	}, activity)
+
+	// fleetd is required
+	host.OrbitNodeKey = nil
+	_, err = svc.RequestHostPprofCapture(ctx, &fleet.HostPprofCapturePayload{HostID: 1, Type: fleet.HostPprofProfileHeap})
+	require.ErrorContains(t, err, fleet.HostPprofCaptureFleetdRequiredErrMsg)
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Request host remote query
+////////////////////////////////////////////////////////////////////////////////
+
+type requestHostRemoteQueryRequest struct {
+	HostID uint `url:"id"`
+	fleet.HostRemoteQueryPayload
+}
+
+type requestHostRemoteQueryResponse struct {
+	RemoteQuery *fleet.HostRemoteQuery `json:"remote_query,omitempty"`
+	Err         error                  `json:"error,omitempty"`
+}
+
+func (r requestHostRemoteQueryResponse) error() error { return r.Err }
+func (r requestHostRemoteQueryResponse) Status() int  { return http.StatusAccepted }
+
+func requestHostRemoteQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*requestHostRemoteQueryRequest)
+	req.HostRemoteQueryPayload.HostID = req.HostID
+	rq, err := svc.RequestHostRemoteQuery(ctx, &req.HostRemoteQueryPayload)
+	if err != nil {
+		return requestHostRemoteQueryResponse{Err: err}, nil
+	}
+	return requestHostRemoteQueryResponse{RemoteQuery: rq}, nil
+}
+
+func (svc *Service) RequestHostRemoteQuery(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error) {
+	// must load the host to get the team to authorize with the proper team id
+	// and to check the team's remote queries settings.
+	host, err := svc.ds.HostLite(ctx, payload.HostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had access to run remote queries (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostRemoteQuery{}, fleet.ActionWrite); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostRemoteQuery{TeamID: host.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the query is sent to osquery by fleetd via the extension socket, it does
+	// not require scripts to be enabled as it only reads the host's state.
+	if host.OrbitNodeKey == nil || *host.OrbitNodeKey == "" {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostRemoteQueryFleetdRequiredErrMsg), http.StatusUnprocessableEntity)
+	}
+	settings, err := svc.remoteQuerySettings(ctx, host.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, fleet.NewUserMessageError(errors.New(fleet.HostRemoteQueryDisabledErrMsg), http.StatusForbidden)
+	}
+	if err := payload.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate remote query")
+	}
+
+	if user := authz.UserFromContext(ctx); user != nil {
+		payload.UserID = &user.ID
+	}
+	rq, err := svc.ds.NewHostRemoteQuery(ctx, payload)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create host remote query")
+	}
+	rq.TeamID = host.TeamID
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeRanHostRemoteQuery{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		ExecutionID:     rq.ExecutionID,
+		Query:           rq.Query,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for host remote query")
+	}
+	return rq, nil
+}
+
+// remoteQuerySettings returns the remote queries settings of the team, or the
+// global settings if teamID is nil.
+func (svc *Service) remoteQuerySettings(ctx context.Context, teamID *uint) (fleet.RemoteQuerySettings, error) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return fleet.RemoteQuerySettings{}, ctxerr.Wrap(ctx, err, "get app config")
+	}
+
+	if teamID != nil {
+		tm, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return fleet.RemoteQuerySettings{}, ctxerr.Wrap(ctx, err, "get team")
+		}
+		tm.ApplyInheritedSettings(appConfig)
+		return tm.Config.RemoteQueries, nil
+	}
+	return appConfig.RemoteQueries, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List host remote queries
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostRemoteQueriesRequest struct {
+	HostID uint `url:"id"`
+}
+
+type listHostRemoteQueriesResponse struct {
+	RemoteQueries []*fleet.HostRemoteQuery `json:"remote_queries"`
+	Err           error                    `json:"error,omitempty"`
+}
+
+func (r listHostRemoteQueriesResponse) error() error { return r.Err }
+
+func listHostRemoteQueriesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listHostRemoteQueriesRequest)
+	rqs, err := svc.ListHostRemoteQueries(ctx, req.HostID)
+	if err != nil {
+		return listHostRemoteQueriesResponse{Err: err}, nil
+	}
+	if rqs == nil {
+		rqs = []*fleet.HostRemoteQuery{} // return empty json array instead of json null
+	}
+	return listHostRemoteQueriesResponse{RemoteQueries: rqs}, nil
+}
+
+func (svc *Service) ListHostRemoteQueries(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		// if error is because the host does not exist, check first if the user
+		// had global access (to prevent leaking valid host ids).
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostRemoteQuery{}, fleet.ActionRead); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.HostRemoteQuery{TeamID: host.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	rqs, err := svc.ds.ListHostRemoteQueries(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host remote queries")
+	}
+	for _, rq := range rqs {
+		rq.TeamID = host.TeamID
+	}
+	return rqs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get host remote query
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostRemoteQueryRequest struct {
+	ExecutionID string `url:"execution_id"`
+}
+
+type getHostRemoteQueryResponse struct {
+	*fleet.HostRemoteQuery
+	Err error `json:"error,omitempty"`
+}
+
+func (r getHostRemoteQueryResponse) error() error { return r.Err }
+
+func getHostRemoteQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostRemoteQueryRequest)
+	rq, err := svc.GetHostRemoteQuery(ctx, req.ExecutionID)
+	if err != nil {
+		return getHostRemoteQueryResponse{Err: err}, nil
+	}
+	return getHostRemoteQueryResponse{HostRemoteQuery: rq}, nil
+}
+
+func (svc *Service) GetHostRemoteQuery(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error) {
+	rq, err := svc.ds.GetHostRemoteQuery(ctx, execID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			if err := svc.authz.Authorize(ctx, &fleet.HostRemoteQuery{}, fleet.ActionRead); err != nil {
+				return nil, err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host remote query")
+	}
+
+	// authorize with the team of the host, if the host was deleted since the
+	// request, only global users can access the results.
+	host, err := svc.ds.HostLite(ctx, rq.HostID)
+	if err != nil && !fleet.IsNotFound(err) {
+		svc.authz.SkipAuthorization(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	if host != nil {
+		rq.TeamID = host.TeamID
+	}
+	if err := svc.authz.Authorize(ctx, rq, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRemoteQueriesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		noTeamHostID = 1
+		team1HostID  = 2
+	)
+	hosts := map[uint]*fleet.Host{
+		noTeamHostID: {ID: noTeamHostID, Platform: "darwin", OrbitNodeKey: ptr.String("abc")},
+		team1HostID:  {ID: team1HostID, Platform: "darwin", OrbitNodeKey: ptr.String("def"), TeamID: ptr.Uint(1)},
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{RemoteQueries: fleet.RemoteQuerySettings{Enabled: true}}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{RemoteQueries: fleet.RemoteQuerySettings{Enabled: true}}}, nil
+	}
+	ds.NewHostRemoteQueryFunc = func(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error) {
+		return &fleet.HostRemoteQuery{HostID: payload.HostID, ExecutionID: "x", Query: payload.Query}, nil
+	}
+	ds.ListHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+		return nil, nil
+	}
+	ds.GetHostRemoteQueryFunc = func(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error) {
+		hostID := uint(noTeamHostID)
+		if execID == "team1" {
+			hostID = team1HostID
+		}
+		return &fleet.HostRemoteQuery{HostID: hostID, ExecutionID: execID, Status: fleet.HostRemoteQueryStatusCompleted}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalRead  bool
+		shouldFailGlobalWrite bool
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, true, true, true},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, true, true, true},
+		{"team admin, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, true, false, false},
+		{"team maintainer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, true, true, false, false},
+		{"team observer, belongs to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, true, true, true},
+		{"team admin, DOES NOT belong to team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, true, true, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			payload := fleet.HostRemoteQueryPayload{HostID: noTeamHostID, Query: "SELECT 1"}
+			_, err := svc.RequestHostRemoteQuery(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			payload.HostID = team1HostID
+			_, err = svc.RequestHostRemoteQuery(ctx, &payload)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ListHostRemoteQueries(ctx, noTeamHostID)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.ListHostRemoteQueries(ctx, team1HostID)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.GetHostRemoteQuery(ctx, "no-team")
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.GetHostRemoteQuery(ctx, "team1")
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+}
+
+func TestRequestHostRemoteQuery(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	host := &fleet.Host{ID: 1, Platform: "ubuntu", Hostname: "linux", OrbitNodeKey: ptr.String("abc"), ScriptsEnabled: ptr.Bool(false)}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	globalSettings := fleet.RemoteQuerySettings{Enabled: false}
+	teamSettings := fleet.RemoteQuerySettings{Enabled: true}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{RemoteQueries: globalSettings}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{RemoteQueries: teamSettings}}, nil
+	}
+	var gotPayload *fleet.HostRemoteQueryPayload
+	ds.NewHostRemoteQueryFunc = func(ctx context.Context, payload *fleet.HostRemoteQueryPayload) (*fleet.HostRemoteQuery, error) {
+		gotPayload = payload
+		return &fleet.HostRemoteQuery{HostID: payload.HostID, ExecutionID: "x", Query: payload.Query}, nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	// disabled globally for hosts with no team
+	_, err := svc.RequestHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{HostID: 1, Query: "SELECT 1"})
+	require.ErrorContains(t, err, fleet.HostRemoteQueryDisabledErrMsg)
+	require.False(t, ds.NewHostRemoteQueryFuncInvoked)
+
+	// enabled for the host's team
+	host.TeamID = ptr.Uint(1)
+	_, err = svc.RequestHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{HostID: 1, Query: "SELECT 1; SELECT 2"})
+	require.ErrorContains(t, err, "Only a single SQL statement")
+	require.False(t, ds.NewHostRemoteQueryFuncInvoked)
+
+	// scripts do not need to be enabled on the agent
+	rq, err := svc.RequestHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{HostID: 1, Query: " SELECT * FROM os_version; "})
+	require.NoError(t, err)
+	require.Equal(t, "x", rq.ExecutionID)
+	require.Equal(t, uint(1), *rq.TeamID)
+	require.Equal(t, uint(1), *gotPayload.UserID)
+	require.Equal(t, "SELECT * FROM os_version", gotPayload.Query)
+	require.Equal(t, fleet.ActivityTypeRanHostRemoteQuery{
+		HostID:          1,
+		HostDisplayName: "linux",
+		ExecutionID:     "x",
+		Query:           "SELECT * FROM os_version",
+	}, activity)
+
+	// disabled for the host's team
+	teamSettings.Enabled = false
+	_, err = svc.RequestHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{HostID: 1, Query: "SELECT 1"})
+	require.ErrorContains(t, err, fleet.HostRemoteQueryDisabledErrMsg)
+
+	// fleetd is required
+	host.OrbitNodeKey = nil
+	_, err = svc.RequestHostRemoteQuery(ctx, &fleet.HostRemoteQueryPayload{HostID: 1, Query: "SELECT 1"})
+	require.ErrorContains(t, err, fleet.HostRemoteQueryFleetdRequiredErrMsg)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/externalsvc"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// EnrichHostWarranties looks up the warranty of the hosts made by the vendors
+// configured in the warranty integrations and stores their purchase and
+// warranty end dates. It is meant to be called by a cron job, each run looks
+// up at most fleet.HostWarrantyLookupBatchSize hosts whose warranty was never
+// looked up or was looked up more than fleet.HostWarrantyRefreshInterval ago.
+func EnrichHostWarranties(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if len(appConfig.Integrations.Warranty) == 0 {
+		return nil
+	}
+
+	clients := make(map[fleet.WarrantyVendor]*externalsvc.WarrantyClient, len(appConfig.Integrations.Warranty))
+	vendors := make([]fleet.WarrantyVendor, 0, len(appConfig.Integrations.Warranty))
+	for _, intg := range appConfig.Integrations.Warranty {
+		client, err := externalsvc.NewWarrantyClient(&externalsvc.WarrantyOptions{
+			Vendor:   string(intg.Vendor),
+			URL:      intg.URL,
+			ClientID: intg.ClientID,
+			APIKey:   intg.APIKey,
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", "create warranty client", "vendor", intg.Vendor, "err", err)
+			continue
+		}
+		clients[intg.Vendor] = client
+		vendors = append(vendors, intg.Vendor)
+	}
+
+	hosts, err := ds.ListHostsForWarrantyLookup(ctx, vendors, time.Now().Add(-fleet.HostWarrantyRefreshInterval), fleet.HostWarrantyLookupBatchSize)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list hosts for warranty lookup")
+	}
+
+	warranties := make([]*fleet.HostWarranty, 0, len(hosts))
+	for _, host := range hosts {
+		vendor, ok := fleet.WarrantyVendorForHardwareVendor(host.HardwareVendor)
+		if !ok || clients[vendor] == nil {
+			continue
+		}
+
+		hw := &fleet.HostWarranty{HostID: host.ID, Vendor: vendor}
+		w, err := clients[vendor].LookupWarranty(ctx, host.HardwareSerial)
+		switch {
+		case errors.Is(err, externalsvc.ErrWarrantyNotFound):
+			// record the lookup so that the host is not looked up again before
+			// the refresh interval.
+			level.Debug(logger).Log("msg", "warranty not found", "host_id", host.ID, "vendor", vendor, "err", err)
+		case err != nil:
+			// the error is likely to happen for all hosts of that vendor (e.g.
+			// invalid credentials or rate limit), skip the vendor for this run.
+			level.Error(logger).Log("msg", "lookup warranty", "host_id", host.ID, "vendor", vendor, "err", err)
+			delete(clients, vendor)
+			continue
+		default:
+			hw.PurchaseDate = w.PurchaseDate
+			hw.WarrantyEndDate = w.EndDate
+		}
+		warranties = append(warranties, hw)
+	}
+
+	if err := ds.UpsertHostWarranties(ctx, warranties); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host warranties")
+	}
+	level.Debug(logger).Log("msg", "enriched host warranties", "count", len(warranties))
+	return nil
+}
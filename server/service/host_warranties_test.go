@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrichHostWarranties(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	lenovoSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("Serial") {
+		case "LEN1":
+			_, _ = w.Write([]byte(`{"ErrorCode": 0, "Purchased": "2023-01-10T00:00:00Z", "Warranty": [{"End": "2026-01-09T00:00:00Z"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"ErrorCode": 100, "ErrorMessage": "Invalid serial number"}`))
+		}
+	}))
+	defer lenovoSrv.Close()
+
+	var dellCalls int
+	dellSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dellCalls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer dellSrv.Close()
+
+	var intgs []*fleet.WarrantyIntegration
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{Integrations: fleet.Integrations{Warranty: intgs}}, nil
+	}
+	ds.ListHostsForWarrantyLookupFunc = func(ctx context.Context, vendors []fleet.WarrantyVendor, checkedBefore time.Time, limit int) ([]*fleet.Host, error) {
+		require.ElementsMatch(t, []fleet.WarrantyVendor{fleet.WarrantyVendorDell, fleet.WarrantyVendorLenovo}, vendors)
+		require.WithinDuration(t, time.Now().Add(-fleet.HostWarrantyRefreshInterval), checkedBefore, time.Minute)
+		return []*fleet.Host{
+			{ID: 1, HardwareVendor: "LENOVO", HardwareSerial: "LEN1"},
+			{ID: 2, HardwareVendor: "Dell Inc.", HardwareSerial: "DELL1"},
+			{ID: 3, HardwareVendor: "Dell Inc.", HardwareSerial: "DELL2"},
+			{ID: 4, HardwareVendor: "LENOVO", HardwareSerial: "LEN2"},
+			{ID: 5, HardwareVendor: "Apple Inc.", HardwareSerial: "C02"},
+		}, nil
+	}
+	var upserted []*fleet.HostWarranty
+	ds.UpsertHostWarrantiesFunc = func(ctx context.Context, warranties []*fleet.HostWarranty) error {
+		upserted = warranties
+		return nil
+	}
+
+	// no integration, nothing to do
+	require.NoError(t, EnrichHostWarranties(ctx, ds, kitlog.NewNopLogger()))
+	require.False(t, ds.ListHostsForWarrantyLookupFuncInvoked)
+
+	intgs = []*fleet.WarrantyIntegration{
+		{Vendor: fleet.WarrantyVendorLenovo, URL: lenovoSrv.URL, APIKey: "token"},
+		{Vendor: fleet.WarrantyVendorDell, URL: dellSrv.URL, ClientID: "id", APIKey: "wrong"},
+	}
+	require.NoError(t, EnrichHostWarranties(ctx, ds, kitlog.NewNopLogger()))
+	require.True(t, ds.UpsertHostWarrantiesFuncInvoked)
+
+	// the Dell hosts are skipped after the first failure, the Apple host is
+	// not configured.
+	require.Equal(t, 1, dellCalls)
+	require.Len(t, upserted, 2)
+	require.Equal(t, uint(1), upserted[0].HostID)
+	require.Equal(t, fleet.WarrantyVendorLenovo, upserted[0].Vendor)
+	require.Equal(t, time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), *upserted[0].PurchaseDate)
+	require.Equal(t, time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), *upserted[0].WarrantyEndDate)
+	// not found is recorded without dates
+	require.Equal(t, &fleet.HostWarranty{HostID: 4, Vendor: fleet.WarrantyVendorLenovo}, upserted[1])
+}
@@ -6,8 +6,8 @@ import (
 	"crypto/tls"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -17,17 +17,20 @@ import (
 	"github.com/fleetdm/fleet/v4/server/authz"
 	authzctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
 	"github.com/fleetdm/fleet/v4/server/contexts/license"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mdm"
+	apple_mdm "github.com/fleetdm/fleet/v4/server/mdm/apple"
 	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
 	mdmlifecycle "github.com/fleetdm/fleet/v4/server/mdm/lifecycle"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/worker"
 	"github.com/go-kit/log/level"
 	"github.com/gocarina/gocsv"
+	"github.com/google/uuid"
 )
 
 // HostDetailResponse is the response struct that contains the full host information
@@ -292,7 +295,7 @@ func (svc *Service) DeleteHosts(ctx context.Context, ids []uint, filter *map[str
 
 		mdmLifecycle := mdmlifecycle.New(svc.ds, svc.logger)
 		for _, host := range hosts {
-			if host.Platform == "darwin" || host.Platform == "windows" {
+			if fleet.IsApplePlatform(host.Platform) || host.Platform == "windows" {
 				err := mdmLifecycle.Do(ctx, mdmlifecycle.HostOptions{
 					Action:   mdmlifecycle.HostActionDelete,
 					Host:     host,
@@ -746,7 +749,7 @@ func (svc *Service) DeleteHost(ctx context.Context, id uint) error {
 		return ctxerr.Wrap(ctx, err, "delete host")
 	}
 
-	if host.Platform == "windows" || host.Platform == "darwin" {
+	if host.Platform == "windows" || fleet.IsApplePlatform(host.Platform) {
 		mdmLifecycle := mdmlifecycle.New(svc.ds, svc.logger)
 		err = mdmLifecycle.Do(ctx, mdmlifecycle.HostOptions{
 			Action:   mdmlifecycle.HostActionDelete,
@@ -817,10 +820,33 @@ func (svc *Service) AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs []
 			return ctxerr.Wrap(ctx, err, "queue macos setup assistant hosts transferred job")
 		}
 	}
+	if err := svc.queueHostTransferJob(ctx, teamID, hostIDs); err != nil {
+		return err
+	}
 
 	return svc.createTransferredHostsActivity(ctx, teamID, hostIDs, nil)
 }
 
+// queues the job that reconciles the platform-specific state (MDM profiles,
+// policies) of the transferred hosts, and triggers the worker so that it is
+// processed without waiting for its next scheduled run.
+func (svc *Service) queueHostTransferJob(ctx context.Context, teamID *uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+	if err := worker.QueueHostTransferJob(ctx, svc.ds, svc.logger, teamID, hostIDs); err != nil {
+		return ctxerr.Wrap(ctx, err, "queue host transfer job")
+	}
+	if svc.cronSchedulesService != nil {
+		// the trigger fails if the worker is already running, in which case the
+		// job will be processed on its next run.
+		if err := svc.cronSchedulesService.TriggerCronSchedule(string(fleet.CronWorkerIntegrations)); err != nil {
+			level.Debug(svc.logger).Log("msg", "trigger worker integrations", "err", err)
+		}
+	}
+	return nil
+}
+
 // creates the transferred hosts activity if hosts were transferred, taking
 // care of loading the team name and the hosts names if necessary (hostNames
 // may be passed as empty if they were not available to the caller, such as in
@@ -952,6 +978,9 @@ func (svc *Service) AddHostsToTeamByFilter(ctx context.Context, teamID *uint, fi
 			return ctxerr.Wrap(ctx, err, "queue macos setup assistant hosts transferred job")
 		}
 	}
+	if err := svc.queueHostTransferJob(ctx, teamID, hostIDs); err != nil {
+		return err
+	}
 
 	return svc.createTransferredHostsActivity(ctx, teamID, hostIDs, hostNames)
 }
@@ -980,12 +1009,14 @@ func refetchHostEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 }
 
 func (svc *Service) RefetchHost(ctx context.Context, id uint) error {
+	var host *fleet.Host
 	if !svc.authz.IsAuthenticatedWith(ctx, authzctx.AuthnDeviceToken) {
 		if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
 			return err
 		}
 
-		host, err := svc.ds.HostLite(ctx, id)
+		var err error
+		host, err = svc.ds.HostLite(ctx, id)
 		if err != nil {
 			return ctxerr.Wrap(ctx, err, "find host for refetch")
 		}
@@ -995,12 +1026,68 @@ func (svc *Service) RefetchHost(ctx context.Context, id uint) error {
 		if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
 			return err
 		}
+	} else {
+		var ok bool
+		host, ok = hostctx.FromContext(ctx)
+		if !ok {
+			return ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+		}
 	}
 
 	if err := svc.ds.UpdateHostRefetchRequested(ctx, id, true); err != nil {
 		return ctxerr.Wrap(ctx, err, "save host")
 	}
 
+	if fleet.IsApplePlatform(host.Platform) {
+		if err := svc.refetchAppleMDMDeviceInformation(ctx, host); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refetchAppleMDMDeviceInformation sends a DeviceInformation MDM command to
+// the Apple host if it is enrolled in Fleet MDM, so that its details are
+// refreshed without waiting for the next osquery check-in, a CertificateList
+// command to refresh its certificates, and a SecurityInfo command to the macOS
+// hosts to refresh their security information. The results are ingested by
+// CommandAndReportResults.
+func (svc *Service) refetchAppleMDMDeviceInformation(ctx context.Context, host *fleet.Host) error {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if !appConfig.MDM.EnabledAndConfigured {
+		return nil
+	}
+
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host mdm")
+	}
+	if !hostMDM.IsFleetEnrolled() {
+		return nil
+	}
+
+	// a failure to send the push notification is not an error, the command is
+	// enqueued and will be delivered on the next check-in of the host.
+	var apnsErr *apple_mdm.APNSDeliveryError
+	err = svc.mdmAppleCommander.DeviceInformation(ctx, []string{host.UUID}, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString(), nil)
+	if err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send DeviceInformation command to refetch host")
+	}
+	err = svc.mdmAppleCommander.CertificateList(ctx, []string{host.UUID}, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString())
+	if err != nil && !errors.As(err, &apnsErr) {
+		return ctxerr.Wrap(ctx, err, "send CertificateList command to refetch host")
+	}
+
+	if host.Platform == "darwin" {
+		err = svc.mdmAppleCommander.SecurityInfo(ctx, []string{host.UUID}, fleet.RefetchMDMCommandUUIDPrefix+uuid.NewString())
+		if err != nil && !errors.As(err, &apnsErr) {
+			return ctxerr.Wrap(ctx, err, "send SecurityInfo command to refetch host")
+		}
+	}
 	return nil
 }
 
@@ -1101,7 +1188,7 @@ func (svc *Service) getHostDetails(ctx context.Context, host *fleet.Host, opts f
 				profiles = append(profiles, p.ToHostMDMProfile())
 			}
 
-		case "darwin":
+		case "darwin", "ios", "ipados":
 			if ac.MDM.EnabledAndConfigured {
 				profs, err := svc.ds.GetHostMDMAppleProfiles(ctx, host.UUID)
 				if err != nil {
@@ -1137,6 +1224,20 @@ func (svc *Service) getHostDetails(ctx context.Context, host *fleet.Host, opts f
 	}
 	host.MDM.MacOSSetup = macOSSetup
 
+	if ac.MDM.EnabledAndConfigured && host.Platform == "darwin" {
+		fleetdInstall, err := svc.ds.GetHostMDMFleetdInstall(ctx, host.UUID)
+		if err != nil && !fleet.IsNotFound(err) {
+			return nil, ctxerr.Wrap(ctx, err, "get host mdm fleetd install")
+		}
+		host.MDM.FleetdInstall = fleetdInstall
+
+		securityInfo, err := svc.ds.GetHostMDMAppleSecurityInfo(ctx, host.ID)
+		if err != nil && !fleet.IsNotFound(err) {
+			return nil, ctxerr.Wrap(ctx, err, "get host mdm apple security info")
+		}
+		host.MDM.MacOSSecurityInfo = securityInfo
+	}
+
 	mdmActions, err := svc.ds.GetHostLockWipeStatus(ctx, host)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "get host mdm lock/wipe status")
@@ -1164,6 +1265,25 @@ func (svc *Service) getHostDetails(ctx context.Context, host *fleet.Host, opts f
 		host.MDM.PendingAction = ptr.String("wipe")
 	}
 
+	// network isolation status, independent of the lock and wipe status
+	host.MDM.IsolationStatus = ptr.String("not_isolated")
+	host.MDM.PendingIsolationAction = ptr.String("")
+	if mdmActions.IsIsolated() {
+		host.MDM.IsolationStatus = ptr.String("isolated")
+	}
+	switch {
+	case mdmActions.IsPendingIsolate():
+		host.MDM.PendingIsolationAction = ptr.String("isolate")
+	case mdmActions.IsPendingUnisolate():
+		host.MDM.PendingIsolationAction = ptr.String("unisolate")
+	}
+
+	withheld, err := svc.osqueryConfigWithheld(ctx, host)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "check whether osquery config is withheld")
+	}
+	host.MDM.OsqueryConfigWithheld = &withheld
+
 	host.Policies = policies
 	return &fleet.HostDetail{
 		Host:      *host,
@@ -1680,14 +1800,41 @@ type hostsReportRequest struct {
 type hostsReportResponse struct {
 	Columns []string              `json:"-"` // used to control the generated csv, see the hijackRender method
 	Hosts   []*fleet.HostResponse `json:"-"` // they get rendered explicitly, in csv
-	Err     error                 `json:"error,omitempty"`
+	// CustomFields are the host custom fields included in the csv.
+	CustomFields []fleet.HostCustomFieldDefinition `json:"-"`
+	Err          error                             `json:"error,omitempty"`
 }
 
 func (r hostsReportResponse) error() error { return r.Err }
 
 func (r hostsReportResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	recs, err := hostsCSVRecords(ctx, r.Hosts, r.Columns, r.CustomFields)
+	if err != nil {
+		var bre *fleet.BadRequestError
+		if !errors.As(err, &bre) {
+			logging.WithErr(ctx, err)
+			err = ctxerr.New(ctx, "failed to generate CSV file")
+		}
+		encodeError(ctx, err, w)
+		return
+	}
+
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="Hosts %s.csv"`, time.Now().Format("2006-01-02")))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if err := csv.NewWriter(w).WriteAll(recs); err != nil {
+		logging.WithErr(ctx, err)
+	}
+}
+
+// hostsCSVRecords returns the header and rows of the hosts report, restricted
+// to the provided columns if any. The values of the custom fields are added
+// as one column per field, named with the hostCustomFieldCSVPrefix prefix.
+func hostsCSVRecords(ctx context.Context, hosts []*fleet.HostResponse, columns []string, customFields []fleet.HostCustomFieldDefinition) ([][]string, error) {
 	// post-process the Device Mappings for CSV rendering
-	for _, h := range r.Hosts {
+	for _, h := range hosts {
 		if h.DeviceMapping != nil {
 			// return the list of emails, comma-separated, as part of that single CSV field
 			var dms []struct {
@@ -1711,66 +1858,55 @@ func (r hostsReportResponse) hijackRender(ctx context.Context, w http.ResponseWr
 	}
 
 	var buf bytes.Buffer
-	if err := gocsv.Marshal(r.Hosts, &buf); err != nil {
-		logging.WithErr(ctx, err)
-		encodeError(ctx, ctxerr.New(ctx, "failed to generate CSV file"), w)
-		return
+	if err := gocsv.Marshal(hosts, &buf); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal hosts")
 	}
 
-	returnAll := len(r.Columns) == 0
-
-	var outRows [][]string
-	if !returnAll {
-		// read back the CSV to filter out any unwanted columns
-		recs, err := csv.NewReader(&buf).ReadAll()
-		if err != nil {
-			logging.WithErr(ctx, err)
-			encodeError(ctx, ctxerr.New(ctx, "failed to generate CSV file"), w)
-			return
-		}
-
-		if len(recs) > 0 {
-			// map the header names to their field index
-			hdrs := make(map[string]int, len(recs))
-			for i, hdr := range recs[0] {
-				hdrs[hdr] = i
-			}
-
-			outRows = make([][]string, len(recs))
-			for i, rec := range recs {
-				for _, col := range r.Columns {
-					colIx, ok := hdrs[col]
-					if !ok {
-						// invalid column name - it would be nice to catch this in the
-						// endpoint before processing the results, but it would require
-						// duplicating the list of columns from the Host's struct tags to a
-						// map and keep this in sync, for what is essentially a programmer
-						// mistake that should be caught and corrected early.
-						encodeError(ctx, &fleet.BadRequestError{Message: fmt.Sprintf("invalid column name: %q", col)}, w)
-						return
-					}
-					outRows[i] = append(outRows[i], rec[colIx])
-				}
+	// read back the CSV to filter out any unwanted columns
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "read back hosts CSV")
+	}
+	if len(recs) > 0 {
+		for _, def := range customFields {
+			recs[0] = append(recs[0], hostCustomFieldCSVPrefix+def.Name)
+			for i, h := range hosts {
+				recs[i+1] = append(recs[i+1], h.CustomFields[def.Name])
 			}
 		}
 	}
+	if len(columns) == 0 || len(recs) == 0 {
+		return recs, nil
+	}
 
-	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="Hosts %s.csv"`, time.Now().Format("2006-01-02")))
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusOK)
-
-	var err error
-	if returnAll {
-		_, err = io.Copy(w, &buf)
-	} else {
-		err = csv.NewWriter(w).WriteAll(outRows)
+	// map the header names to their field index
+	hdrs := make(map[string]int, len(recs[0]))
+	for i, hdr := range recs[0] {
+		hdrs[hdr] = i
 	}
-	if err != nil {
-		logging.WithErr(ctx, err)
+
+	outRows := make([][]string, len(recs))
+	for i, rec := range recs {
+		for _, col := range columns {
+			colIx, ok := hdrs[col]
+			if !ok {
+				// invalid column name - it would be nice to catch this in the
+				// endpoint before processing the results, but it would require
+				// duplicating the list of columns from the Host's struct tags to a
+				// map and keep this in sync, for what is essentially a programmer
+				// mistake that should be caught and corrected early.
+				return nil, &fleet.BadRequestError{Message: fmt.Sprintf("invalid column name: %q", col)}
+			}
+			outRows[i] = append(outRows[i], rec[colIx])
+		}
 	}
+	return outRows, nil
 }
 
+// hostCustomFieldCSVPrefix is the prefix of the name of the custom field
+// columns in the hosts report.
+const hostCustomFieldCSVPrefix = "custom_field_"
+
 func hostsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*hostsReportRequest)
 
@@ -1799,11 +1935,20 @@ func hostsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 			if rawCol == "device_mapping" {
 				req.Opts.DeviceMapping = true
 			}
+			if rawCol == "notes" || strings.HasPrefix(rawCol, hostCustomFieldCSVPrefix) {
+				req.Opts.PopulateCustomFields = true
+			}
+			if rawCol == "purchase_date" || rawCol == "warranty_end_date" {
+				req.Opts.PopulateWarranty = true
+			}
 		}
 	}
 	if len(cols) == 0 {
-		// enable device_mapping retrieval, as no column means all columns
+		// enable device_mapping, custom fields and warranty retrieval, as no
+		// column means all columns
 		req.Opts.DeviceMapping = true
+		req.Opts.PopulateCustomFields = true
+		req.Opts.PopulateWarranty = true
 	}
 
 	var (
@@ -1820,12 +1965,17 @@ func hostsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 		return hostsReportResponse{Err: err}, nil
 	}
 
+	appConfig, err := svc.AppConfigObfuscated(ctx)
+	if err != nil {
+		return hostsReportResponse{Err: err}, nil
+	}
+
 	hostResps := make([]*fleet.HostResponse, len(hosts))
 	for i, h := range hosts {
 		hr := fleet.HostResponseForHost(ctx, svc, h)
 		hostResps[i] = hr
 	}
-	return hostsReportResponse{Columns: cols, Hosts: hostResps}, nil
+	return hostsReportResponse{Columns: cols, Hosts: hostResps, CustomFields: appConfig.HostCustomFields}, nil
 }
 
 type osVersionsRequest struct {
@@ -2105,9 +2255,21 @@ func (svc *Service) HostEncryptionKey(ctx context.Context, id uint) (*fleet.Host
 		return nil, err
 	}
 
-	// The middleware checks that either Apple or Windows MDM are configured and
-	// enabled, but here we must check if the specific one is enabled for that
-	// particular host's platform.
+	// Linux hosts escrow their LUKS passphrase without MDM, it is encrypted
+	// with the server private key and decrypted by the datastore.
+	if host.FleetPlatform() == "linux" {
+		key, err := svc.ds.GetHostLUKSDiskEncryptionKey(ctx, id)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "getting host encryption key")
+		}
+		if err := svc.newReadHostDiskEncryptionKeyActivity(ctx, host); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	// Check that the MDM solution that escrows the key of that particular
+	// host's platform is configured and enabled.
 	var decryptCert *tls.Certificate
 	switch host.FleetPlatform() {
 	case "windows":
@@ -2149,7 +2311,60 @@ func (svc *Service) HostEncryptionKey(ctx context.Context, id uint) (*fleet.Host
 	}
 	key.DecryptedValue = string(decryptedKey)
 
-	err = svc.ds.NewActivity(
+	if err := svc.newReadHostDiskEncryptionKeyActivity(ctx, host); err != nil {
+		return nil, err
+	}
+
+	// The FileVault key was disclosed, rotate it. The new key is escrowed
+	// when the host reports it. Failing to rotate the key doesn't prevent
+	// reading it.
+	if host.FleetPlatform() == "darwin" {
+		if err := svc.rotateHostFileVaultKey(ctx, host, key.DecryptedValue, decryptCert.Leaf.Raw); err != nil {
+			logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "rotate host FileVault key"))
+		}
+	}
+
+	return key, nil
+}
+
+// rotateHostFileVaultKey sends the RotateFileVaultKey command to the host,
+// unless a rotation is already pending. The host encrypts the new key with
+// the certificate used to escrow the keys, replyCertDER.
+func (svc *Service) rotateHostFileVaultKey(ctx context.Context, host *fleet.Host, currentKey string, replyCertDER []byte) error {
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	if hostMDM == nil || !hostMDM.IsFleetEnrolled() {
+		return nil
+	}
+
+	cmdUUID := uuid.NewString()
+	pending, err := svc.ds.SetHostDiskEncryptionKeyRotationPending(ctx, host.ID, cmdUUID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host disk encryption key rotation pending")
+	}
+	if !pending {
+		return nil
+	}
+
+	if err := svc.mdmAppleCommander.RotateFileVaultKey(ctx, []string{host.UUID}, cmdUUID, currentKey, replyCertDER); err != nil {
+		// the command is delivered when the host checks in
+		var apnsErr *apple_mdm.APNSDeliveryError
+		if errors.As(err, &apnsErr) {
+			return nil
+		}
+		// the command wasn't enqueued, clear the pending rotation
+		if clearErr := svc.ds.UpdateHostDiskEncryptionKeyFromRotateResult(ctx, host.UUID, cmdUUID, ""); clearErr != nil {
+			return ctxerr.Wrap(ctx, clearErr, "clear host disk encryption key rotation")
+		}
+		return ctxerr.Wrap(ctx, err, "enqueue RotateFileVaultKey command")
+	}
+	return nil
+}
+
+func (svc *Service) newReadHostDiskEncryptionKeyActivity(ctx context.Context, host *fleet.Host) error {
+	err := svc.ds.NewActivity(
 		ctx,
 		authz.UserFromContext(ctx),
 		fleet.ActivityTypeReadHostDiskEncryptionKey{
@@ -2158,10 +2373,9 @@ func (svc *Service) HostEncryptionKey(ctx context.Context, id uint) (*fleet.Host
 		},
 	)
 	if err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "create read host disk encryption key activity")
+		return ctxerr.Wrap(ctx, err, "create read host disk encryption key activity")
 	}
-
-	return key, nil
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 	"testing"
 	"time"
@@ -21,9 +22,13 @@ import (
 	"github.com/fleetdm/fleet/v4/server/mdm/apple/mobileconfig"
 	nanodep_client "github.com/fleetdm/fleet/v4/server/mdm/nanodep/client"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanodep/tokenpki"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/log/stdlogfmt"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
+	nanomdm_pushsvc "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/push/service"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/groob/plist"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mozilla.org/pkcs7"
@@ -108,6 +113,12 @@ func TestHostDetailsMDMAppleDiskEncryption(t *testing.T) {
 	ds.ListHostBatteriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostBattery, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, newNotFoundError()
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 		return &fleet.HostLockWipeStatus{}, nil
 	}
@@ -385,6 +396,12 @@ func TestHostDetailsOSSettings(t *testing.T) {
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hid uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 		return &fleet.HostLockWipeStatus{}, nil
 	}
@@ -494,6 +511,12 @@ func TestHostDetailsOSSettingsWindowsOnly(t *testing.T) {
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hid uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
 		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
 	}
@@ -600,6 +623,9 @@ func TestHostAuth(t *testing.T) {
 	ds.ListMDMAppleDEPSerialsInHostIDsFunc = func(ctx context.Context, hids []uint) ([]string, error) {
 		return nil, nil
 	}
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
 	ds.TeamFunc = func(ctx context.Context, id uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: id}, nil
 	}
@@ -765,6 +791,84 @@ func TestHostAuth(t *testing.T) {
 	// List, GetHostSummary work for all
 }
 
+func TestHostAuthTenant(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, TeamID: ptr.Uint(1)}, // team of the tenant
+		2: {ID: 2, TeamID: ptr.Uint(2)}, // team of another tenant
+		3: {ID: 3},                      // no team
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.LoadHostSoftwareFunc = func(ctx context.Context, host *fleet.Host, includeCVEScores bool) error {
+		return nil
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return nil, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) (packs []*fleet.Pack, err error) {
+		return nil, nil
+	}
+	ds.ListHostBatteriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostBattery, error) {
+		return nil, nil
+	}
+	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
+		return &fleet.HostLockWipeStatus{}, nil
+	}
+	ds.DeleteHostFunc = func(ctx context.Context, hid uint) error {
+		return nil
+	}
+	ds.UpdateHostRefetchRequestedFunc = func(ctx context.Context, id uint, value bool) error {
+		return nil
+	}
+
+	// a global admin of the tenant only has access to the hosts of the teams
+	// of its tenant.
+	user := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1}}
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: user})
+	opts := fleet.HostDetailOptions{}
+
+	for _, tc := range []struct {
+		hostID     uint
+		shouldFail bool
+	}{
+		{1, false},
+		{2, true},
+		{3, true},
+	} {
+		_, err := svc.GetHost(ctx, tc.hostID, opts)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		_, err = svc.GetHostLite(ctx, tc.hostID)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		err = svc.RefetchHost(ctx, tc.hostID)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		err = svc.DeleteHost(ctx, tc.hostID)
+		checkAuthErr(t, tc.shouldFail, err)
+	}
+
+	// hosts cannot be transferred to a team of another tenant
+	ds.AddHostsToTeamFunc = func(ctx context.Context, teamID *uint, hostIDs []uint) error {
+		return nil
+	}
+	ds.ListHostsLiteByIDsFunc = func(ctx context.Context, ids []uint) ([]*fleet.Host, error) {
+		return []*fleet.Host{hosts[1]}, nil
+	}
+	err := svc.AddHostsToTeam(ctx, ptr.Uint(2), []uint{1}, false)
+	checkAuthErr(t, true, err)
+}
+
 func TestListHosts(t *testing.T) {
 	ds := new(mock.Store)
 	svc, ctx := newTestService(t, ds, nil, nil)
@@ -867,6 +971,10 @@ func TestAddHostsToTeamByFilter(t *testing.T) {
 	ds.ListMDMAppleDEPSerialsInHostIDsFunc = func(ctx context.Context, hids []uint) ([]string, error) {
 		return nil, nil
 	}
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		assert.Equal(t, "host_transfer", job.Name)
+		return job, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -876,6 +984,7 @@ func TestAddHostsToTeamByFilter(t *testing.T) {
 	require.NoError(t, svc.AddHostsToTeamByFilter(test.UserContext(ctx, test.UserAdmin), expectedTeam, emptyRequest))
 	assert.True(t, ds.ListHostsFuncInvoked)
 	assert.True(t, ds.AddHostsToTeamFuncInvoked)
+	assert.True(t, ds.NewJobFuncInvoked)
 }
 
 func TestAddHostsToTeamByFilterLabel(t *testing.T) {
@@ -904,6 +1013,9 @@ func TestAddHostsToTeamByFilterLabel(t *testing.T) {
 	ds.ListMDMAppleDEPSerialsInHostIDsFunc = func(ctx context.Context, hids []uint) ([]string, error) {
 		return nil, nil
 	}
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		return job, nil
+	}
 	ds.TeamFunc = func(ctx context.Context, id uint) (*fleet.Team, error) {
 		return &fleet.Team{ID: id}, nil
 	}
@@ -1234,6 +1346,9 @@ func TestHostEncryptionKey(t *testing.T) {
 				require.Equal(t, tt.host.ID, id)
 				return tt.host, nil
 			}
+			ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+				return nil, newNotFoundError()
+			}
 
 			ds.GetHostDiskEncryptionKeyFunc = func(ctx context.Context, id uint) (*fleet.HostDiskEncryptionKey, error) {
 				return &fleet.HostDiskEncryptionKey{
@@ -1331,6 +1446,9 @@ func TestHostEncryptionKey(t *testing.T) {
 				ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
 					return &fleet.Host{Platform: c.hostPlatform}, nil
 				}
+				ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+					return nil, newNotFoundError()
+				}
 				ds.GetHostDiskEncryptionKeyFunc = func(ctx context.Context, id uint) (*fleet.HostDiskEncryptionKey, error) {
 					key := base64EncryptedKey
 					if c.hostPlatform == "windows" {
@@ -1357,6 +1475,136 @@ func TestHostEncryptionKey(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("FileVault key rotation", func(t *testing.T) {
+		ds := new(mock.Store)
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+		}
+		host := &fleet.Host{ID: 1, UUID: "mac-uuid", Platform: "darwin"}
+		ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+			return host, nil
+		}
+		ds.GetHostDiskEncryptionKeyFunc = func(ctx context.Context, id uint) (*fleet.HostDiskEncryptionKey, error) {
+			return &fleet.HostDiskEncryptionKey{Base64Encrypted: base64EncryptedKey, Decryptable: ptr.Bool(true)}, nil
+		}
+		ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+			return nil
+		}
+		var hostMDM *fleet.HostMDM
+		ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+			if hostMDM == nil {
+				return nil, newNotFoundError()
+			}
+			return hostMDM, nil
+		}
+		var pendingUUID string
+		ds.SetHostDiskEncryptionKeyRotationPendingFunc = func(ctx context.Context, hostID uint, cmdUUID string) (bool, error) {
+			require.Equal(t, host.ID, hostID)
+			if pendingUUID != "" {
+				return false, nil
+			}
+			pendingUUID = cmdUUID
+			return true, nil
+		}
+		ds.UpdateHostDiskEncryptionKeyFromRotateResultFunc = func(ctx context.Context, hostUUID, cmdUUID, encryptedBase64Key string) error {
+			require.Equal(t, pendingUUID, cmdUUID)
+			require.Empty(t, encryptedBase64Key)
+			pendingUUID = ""
+			return nil
+		}
+
+		mdmStorage := &mock.MDMAppleStore{}
+		pushFactory, _ := newMockAPNSPushProviderFactory()
+		pusher := nanomdm_pushsvc.New(mdmStorage, mdmStorage, pushFactory, stdlogfmt.New())
+		mdmStorage.RetrievePushInfoFunc = func(ctx context.Context, tokens []string) (map[string]*mdm.Push, error) {
+			return map[string]*mdm.Push{}, nil
+		}
+		var enqueueErr error
+		var enqueued []*mdm.Command
+		mdmStorage.EnqueueCommandFunc = func(ctx context.Context, id []string, cmd *mdm.Command) (map[string]error, error) {
+			require.Equal(t, []string{host.UUID}, id)
+			if enqueueErr != nil {
+				return nil, enqueueErr
+			}
+			enqueued = append(enqueued, cmd)
+			return map[string]error{}, nil
+		}
+		svc, ctx := newTestServiceWithConfig(t, ds, fleetCfg, nil, nil, &TestServerOpts{
+			FleetConfig: &fleetCfg,
+			MDMStorage:  mdmStorage,
+			MDMPusher:   pusher,
+		})
+		ctx = test.UserContext(ctx, test.UserAdmin)
+
+		// the key of a host that is not enrolled in Fleet MDM is not rotated
+		_, err := svc.HostEncryptionKey(ctx, host.ID)
+		require.NoError(t, err)
+		require.False(t, ds.SetHostDiskEncryptionKeyRotationPendingFuncInvoked)
+
+		hostMDM = &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}
+		key, err := svc.HostEncryptionKey(ctx, host.ID)
+		require.NoError(t, err)
+		require.Equal(t, recoveryKey, key.DecryptedValue)
+		require.Len(t, enqueued, 1)
+		require.Equal(t, "RotateFileVaultKey", enqueued[0].Command.RequestType)
+		require.Equal(t, pendingUUID, enqueued[0].CommandUUID)
+		var full struct {
+			Command struct {
+				KeyType                    string
+				FileVaultUnlock            struct{ Password string }
+				ReplyEncryptionCertificate []byte
+			}
+		}
+		require.NoError(t, plist.Unmarshal(enqueued[0].Raw, &full))
+		require.Equal(t, "personal", full.Command.KeyType)
+		require.Equal(t, recoveryKey, full.Command.FileVaultUnlock.Password)
+		require.Equal(t, testCert.Raw, full.Command.ReplyEncryptionCertificate)
+
+		// a single rotation is pending at a time
+		_, err = svc.HostEncryptionKey(ctx, host.ID)
+		require.NoError(t, err)
+		require.Len(t, enqueued, 1)
+
+		// failing to enqueue the command doesn't prevent reading the key and
+		// clears the pending rotation
+		pendingUUID = ""
+		enqueueErr = errors.New("enqueue error")
+		key, err = svc.HostEncryptionKey(ctx, host.ID)
+		require.NoError(t, err)
+		require.Equal(t, recoveryKey, key.DecryptedValue)
+		require.True(t, ds.UpdateHostDiskEncryptionKeyFromRotateResultFuncInvoked)
+		require.Empty(t, pendingUUID)
+	})
+
+	t.Run("linux host", func(t *testing.T) {
+		ds := new(mock.Store)
+		ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+			return &fleet.Host{ID: id, Platform: "ubuntu", TeamID: ptr.Uint(1)}, nil
+		}
+		ds.GetHostLUKSDiskEncryptionKeyFunc = func(ctx context.Context, id uint) (*fleet.HostDiskEncryptionKey, error) {
+			return &fleet.HostDiskEncryptionKey{HostID: id, DecryptedValue: "passphrase", KeySlot: ptr.Uint(1)}, nil
+		}
+		var activity *fleet.ActivityTypeReadHostDiskEncryptionKey
+		ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, details fleet.ActivityDetails) error {
+			a := details.(fleet.ActivityTypeReadHostDiskEncryptionKey)
+			activity = &a
+			return nil
+		}
+
+		// no MDM is required to read the key of a Linux host
+		svc, ctx := newTestService(t, ds, nil, nil)
+		key, err := svc.HostEncryptionKey(test.UserContext(ctx, test.UserTeamObserverTeam1), 1)
+		require.NoError(t, err)
+		require.Equal(t, "passphrase", key.DecryptedValue)
+		require.Equal(t, ptr.Uint(1), key.KeySlot)
+		require.NotNil(t, activity)
+		require.Equal(t, uint(1), activity.HostID)
+		require.False(t, ds.AppConfigFuncInvoked)
+
+		_, err = svc.HostEncryptionKey(test.UserContext(ctx, test.UserTeamObserverTeam2), 1)
+		checkAuthErr(t, true, err)
+	})
 }
 
 func TestHostMDMProfileDetail(t *testing.T) {
@@ -1400,6 +1648,12 @@ func TestHostMDMProfileDetail(t *testing.T) {
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hid uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 		return &fleet.HostLockWipeStatus{}, nil
 	}
@@ -1505,6 +1759,12 @@ func TestLockUnlockWipeHostAuth(t *testing.T) {
 	ds.GetHostMDMMacOSSetupFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMMacOSSetup, error) {
 		return nil, nil
 	}
+	ds.GetHostMDMFleetdInstallFunc = func(ctx context.Context, hostUUID string) (*fleet.HostMDMFleetdInstall, error) {
+		return nil, nil
+	}
+	ds.GetHostMDMAppleSecurityInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDMAppleSecurityInfo, error) {
+		return nil, newNotFoundError()
+	}
 	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
 		return &fleet.HostLockWipeStatus{}, nil
 	}
@@ -1523,12 +1783,18 @@ func TestLockUnlockWipeHostAuth(t *testing.T) {
 	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
 		return &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}, nil
 	}
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, id string) (*fleet.NanoEnrollment, error) {
+		return &fleet.NanoEnrollment{ID: id, DeviceID: id, Type: "Device", Enabled: true}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
 	ds.UnlockHostManuallyFunc = func(ctx context.Context, hostID uint, platform string, ts time.Time) error {
 		return nil
 	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
 
 	cases := []struct {
 		name                  string
@@ -1617,16 +1883,21 @@ func TestLockUnlockWipeHostAuth(t *testing.T) {
 			}
 			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
 
-			err := svc.LockHost(ctx, globalHostID)
+			_, err := svc.LockHost(ctx, globalHostID, globalHost.LockWipeConfirmationToken())
 			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
-			err = svc.LockHost(ctx, teamHostID)
+			_, err = svc.LockHost(ctx, teamHostID, teamHost.LockWipeConfirmationToken())
 			checkAuthErr(t, tt.shouldFailTeamWrite, err)
 
 			// Pretend we locked the host
 			ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
-				return &fleet.HostLockWipeStatus{HostFleetPlatform: host.FleetPlatform(), LockMDMCommand: &fleet.MDMCommand{}, LockMDMCommandResult: &fleet.MDMCommandResult{Status: fleet.MDMAppleStatusAcknowledged}}, nil
+				return &fleet.HostLockWipeStatus{HostFleetPlatform: host.FleetPlatform(), UnlockPIN: "123456", LockMDMCommand: &fleet.MDMCommand{}, LockMDMCommandResult: &fleet.MDMCommandResult{Status: fleet.MDMAppleStatusAcknowledged}}, nil
 			}
 
+			_, err = svc.GetHostUnlockPIN(ctx, globalHostID)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.GetHostUnlockPIN(ctx, teamHostID)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
 			_, err = svc.UnlockHost(ctx, globalHostID)
 			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
 			_, err = svc.UnlockHost(ctx, teamHostID)
@@ -1637,14 +1908,268 @@ func TestLockUnlockWipeHostAuth(t *testing.T) {
 				return &fleet.HostLockWipeStatus{}, nil
 			}
 
-			err = svc.WipeHost(ctx, globalHostID)
+			_, err = svc.WipeHost(ctx, globalHostID, globalHost.LockWipeConfirmationToken())
 			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
-			err = svc.WipeHost(ctx, teamHostID)
+			_, err = svc.WipeHost(ctx, teamHostID, teamHost.LockWipeConfirmationToken())
 			checkAuthErr(t, tt.shouldFailTeamWrite, err)
 		})
 	}
 }
 
+func TestLockWipeHostConfirmationAndApproval(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock(time.Now())
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}, Clock: mockClock})
+
+	host := &fleet.Host{ID: 1, UUID: "uuid-1", HardwareSerial: "C02ABC123", TeamID: ptr.Uint(1), Platform: "linux"}
+	ds.HostLiteFunc = func(ctx context.Context, hostID uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.GetHostOrbitInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostOrbitInfo, error) {
+		return &fleet.HostOrbitInfo{ScriptsEnabled: ptr.Bool(true)}, nil
+	}
+	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
+		return &fleet.HostLockWipeStatus{HostFleetPlatform: host.FleetPlatform()}, nil
+	}
+	ds.WipeHostViaScriptFunc = func(ctx context.Context, request *fleet.HostScriptRequestPayload, platform string) error {
+		return nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+	var lockWipeSettings fleet.LockWipeSettings
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{LockWipeSettings: lockWipeSettings}}, nil
+	}
+	var approvalReq *fleet.HostLockWipeApprovalRequest
+	ds.GetHostLockWipeApprovalRequestFunc = func(ctx context.Context, hostID uint, action string) (*fleet.HostLockWipeApprovalRequest, error) {
+		if approvalReq == nil || approvalReq.Action != action {
+			return nil, &notFoundError{}
+		}
+		return approvalReq, nil
+	}
+	ds.NewHostLockWipeApprovalRequestFunc = func(ctx context.Context, hostID uint, action string, userID uint) error {
+		approvalReq = &fleet.HostLockWipeApprovalRequest{HostID: hostID, Action: action, RequestedByUserID: &userID, CreatedAt: mockClock.Now()}
+		return nil
+	}
+	ds.ClaimHostLockWipeApprovalRequestFunc = func(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error) {
+		if approvalReq == nil || approvalReq != req {
+			return false, nil
+		}
+		approvalReq = nil
+		return true, nil
+	}
+
+	admin1 := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	admin2 := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	// the wipe must be confirmed
+	for _, confirmation := range []string{"", "nope", "C02ABC12"} {
+		_, err := svc.WipeHost(admin1, host.ID, confirmation)
+		var iae *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &iae, confirmation)
+		require.ErrorContains(t, err, host.LockWipeConfirmationToken())
+	}
+	require.False(t, ds.WipeHostViaScriptFuncInvoked)
+
+	// with the serial number, no approval required
+	pending, err := svc.WipeHost(admin1, host.ID, "c02abc123")
+	require.NoError(t, err)
+	require.False(t, pending)
+	require.True(t, ds.WipeHostViaScriptFuncInvoked)
+	require.Equal(t, []string{fleet.ActivityTypeWipedHost{}.ActivityName()}, activities)
+	require.False(t, ds.GetHostLockWipeApprovalRequestFuncInvoked)
+
+	// require a second approver for the team
+	lockWipeSettings.RequireSecondApprover = true
+	ds.WipeHostViaScriptFuncInvoked = false
+	activities = nil
+
+	pending, err = svc.WipeHost(admin1, host.ID, host.LockWipeConfirmationToken())
+	require.NoError(t, err)
+	require.True(t, pending)
+	require.False(t, ds.WipeHostViaScriptFuncInvoked)
+	require.NotNil(t, approvalReq)
+	require.Equal(t, []string{fleet.ActivityTypeRequestedHostLockWipeApproval{}.ActivityName()}, activities)
+
+	// the requester can't approve
+	_, err = svc.WipeHost(admin1, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "must be approved by a different user")
+	require.False(t, ds.WipeHostViaScriptFuncInvoked)
+
+	// an expired request is replaced by a new request
+	mockClock.AddTime(fleet.HostLockWipeApprovalExpiry + time.Minute)
+	pending, err = svc.WipeHost(admin2, host.ID, host.LockWipeConfirmationToken())
+	require.NoError(t, err)
+	require.True(t, pending)
+	require.Equal(t, uint(2), *approvalReq.RequestedByUserID)
+
+	// another user approves
+	pending, err = svc.WipeHost(admin1, host.ID, host.LockWipeConfirmationToken())
+	require.NoError(t, err)
+	require.False(t, pending)
+	require.True(t, ds.WipeHostViaScriptFuncInvoked)
+	require.Nil(t, approvalReq)
+
+	// a request approved concurrently by another user is queued only once
+	ds.WipeHostViaScriptFuncInvoked = false
+	_, err = svc.WipeHost(admin2, host.ID, host.LockWipeConfirmationToken())
+	require.NoError(t, err)
+	claim := ds.ClaimHostLockWipeApprovalRequestFunc
+	ds.ClaimHostLockWipeApprovalRequestFunc = func(ctx context.Context, req *fleet.HostLockWipeApprovalRequest) (bool, error) {
+		// the other approval claims the request first
+		approvalReq = nil
+		return claim(ctx, req)
+	}
+	_, err = svc.WipeHost(admin1, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "already approved by another user")
+	require.False(t, ds.WipeHostViaScriptFuncInvoked)
+}
+
+func TestLockWipeHostAppleUserEnrollment(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	host := &fleet.Host{ID: 1, UUID: "uuid-1", HardwareSerial: "C02ABC123", Platform: "darwin"}
+	ds.HostLiteFunc = func(ctx context.Context, hostID uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		return &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}, nil
+	}
+	enrollmentType := fleet.NanoEnrollmentTypeUserEnrollmentDevice
+	ds.GetNanoMDMEnrollmentFunc = func(ctx context.Context, id string) (*fleet.NanoEnrollment, error) {
+		return &fleet.NanoEnrollment{ID: id, DeviceID: id, Type: enrollmentType, Enabled: true}, nil
+	}
+	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
+		return nil, errors.New("lock wipe status")
+	}
+
+	_, err := svc.LockHost(ctx, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "Can't lock the host because it's personally-owned and enrolled via User Enrollment (BYOD).")
+	require.ErrorContains(t, err, fleet.MDMAppleUserEnrollmentLockPINMessage)
+
+	_, err = svc.WipeHost(ctx, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "Can't wipe the host because it's personally-owned and enrolled via User Enrollment (BYOD).")
+	require.ErrorContains(t, err, fleet.MDMAppleUserEnrollmentEraseMessage)
+	require.False(t, ds.GetHostLockWipeStatusFuncInvoked)
+
+	// company-owned hosts can be locked and wiped
+	enrollmentType = "Device"
+	_, err = svc.LockHost(ctx, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "lock wipe status")
+	_, err = svc.WipeHost(ctx, host.ID, host.LockWipeConfirmationToken())
+	require.ErrorContains(t, err, "lock wipe status")
+}
+
+func TestIsolateUnisolateHost(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	host := &fleet.Host{ID: 1, TeamID: ptr.Uint(1), Platform: "ubuntu"}
+	ds.HostLiteFunc = func(ctx context.Context, hostID uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	appConfig := &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com:8080"}}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	ds.GetHostOrbitInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostOrbitInfo, error) {
+		return &fleet.HostOrbitInfo{ScriptsEnabled: ptr.Bool(true)}, nil
+	}
+	status := &fleet.HostLockWipeStatus{}
+	ds.GetHostLockWipeStatusFunc = func(ctx context.Context, host *fleet.Host) (*fleet.HostLockWipeStatus, error) {
+		status.HostFleetPlatform = host.FleetPlatform()
+		return status, nil
+	}
+	var scriptContents string
+	ds.IsolateHostViaScriptFunc = func(ctx context.Context, request *fleet.HostScriptRequestPayload, platform string) error {
+		scriptContents = request.ScriptContents
+		return nil
+	}
+	ds.UnisolateHostViaScriptFunc = func(ctx context.Context, request *fleet.HostScriptRequestPayload, platform string) error {
+		scriptContents = request.ScriptContents
+		return nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+
+	// team observers can't isolate the host, team maintainers can
+	observerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}})
+	err := svc.IsolateHost(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+	err = svc.UnisolateHost(observerCtx, host.ID)
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}})
+
+	// the host is not isolated
+	err = svc.UnisolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Host is not isolated.")
+
+	// the isolation script only allows the Fleet server
+	require.NoError(t, svc.IsolateHost(ctx, host.ID))
+	require.True(t, ds.IsolateHostViaScriptFuncInvoked)
+	require.Contains(t, scriptContents, "iptables")
+	require.Contains(t, scriptContents, `FLEET_SERVER_HOST="fleet.example.com"`)
+	require.Equal(t, []string{fleet.ActivityTypeIsolatedHost{}.ActivityName()}, activities)
+
+	// pending isolation
+	status.IsolateScript = &fleet.HostScriptResult{}
+	err = svc.IsolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending isolate request.")
+	err = svc.UnisolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending isolate request.")
+
+	// isolated
+	status.IsolateScript.ExitCode = ptr.Int64(0)
+	err = svc.IsolateHost(ctx, host.ID)
+	var se interface{ Status() int }
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusConflict, se.Status())
+
+	// the windows script is used for windows hosts
+	host.Platform = "windows"
+	require.NoError(t, svc.UnisolateHost(ctx, host.ID))
+	require.True(t, ds.UnisolateHostViaScriptFuncInvoked)
+	require.Contains(t, scriptContents, "Remove-NetFirewallRule")
+	require.Equal(t, []string{fleet.ActivityTypeIsolatedHost{}.ActivityName(), fleet.ActivityTypeUnisolatedHost{}.ActivityName()}, activities)
+
+	// pending unisolate
+	status.UnisolateScript = &fleet.HostScriptResult{}
+	err = svc.UnisolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Host has pending unisolate request.")
+
+	// scripts must be enabled
+	status = &fleet.HostLockWipeStatus{}
+	appConfig.ServerSettings.ScriptsDisabled = true
+	err = svc.IsolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "running scripts is disabled")
+	appConfig.ServerSettings.ScriptsDisabled = false
+	ds.GetHostOrbitInfoFunc = func(ctx context.Context, hostID uint) (*fleet.HostOrbitInfo, error) {
+		return &fleet.HostOrbitInfo{ScriptsEnabled: ptr.Bool(false)}, nil
+	}
+	err = svc.IsolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "--enable-scripts")
+
+	// unsupported platform
+	host.Platform = "ios"
+	err = svc.IsolateHost(ctx, host.ID)
+	require.ErrorContains(t, err, "Unsupported host platform")
+}
+
 func TestBulkOperationFilterValidation(t *testing.T) {
 	ds := new(mock.Store)
 	svc, ctx := newTestService(t, ds, nil, nil)
@@ -28,6 +28,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/datastore/mysql"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/live_query/live_query_mock"
+	"github.com/fleetdm/fleet/v4/server/mfa"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/service/async"
 	"github.com/fleetdm/fleet/v4/server/service/osquery_utils"
@@ -6242,6 +6243,36 @@ func (s *integrationTestSuite) TestAppConfig() {
 	require.True(t, acResp.ActivityExpirySettings.ActivityExpiryEnabled)
 	require.Equal(t, 42, acResp.ActivityExpirySettings.ActivityExpiryWindow)
 
+	// Invalid session settings.
+	acResp = appConfigResponse{}
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+    "session_settings": {
+        "max_session_lifetime": -1,
+        "idle_timeout": 30
+    }
+  }`), http.StatusUnprocessableEntity, &acResp)
+	s.DoJSON("GET", "/api/latest/fleet/config", nil, http.StatusOK, &acResp)
+	require.Zero(t, acResp.SessionSettings.MaxSessionLifetime)
+	require.Zero(t, acResp.SessionSettings.IdleTimeout)
+
+	// Valid session settings, then reset them.
+	acResp = appConfigResponse{}
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+    "session_settings": {
+        "max_session_lifetime": 1440,
+        "idle_timeout": 30
+    }
+  }`), http.StatusOK, &acResp)
+	require.Equal(t, 1440, acResp.SessionSettings.MaxSessionLifetime)
+	require.Equal(t, 30, acResp.SessionSettings.IdleTimeout)
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+    "session_settings": {
+        "max_session_lifetime": 0,
+        "idle_timeout": 0
+    }
+  }`), http.StatusOK, &acResp)
+	require.Zero(t, acResp.SessionSettings.MaxSessionLifetime)
+
 	// test a change that does clear the agent options (the field is provided but empty).
 	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
 		"agent_options": {}
@@ -7866,7 +7897,7 @@ func (s *integrationTestSuite) TestHostsReportDownload() {
 	res.Body.Close()
 	require.NoError(t, err)
 	require.Len(t, rows, len(hosts)+1) // all hosts + header row
-	assert.Len(t, rows[0], 54)         // total number of cols
+	assert.Len(t, rows[0], 62)         // total number of cols
 
 	const (
 		idCol        = 3
@@ -8956,7 +8987,7 @@ func setOrbitEnrollment(t *testing.T, h *fleet.Host, ds fleet.Datastore) string
 	}, orbitKey, nil)
 	require.NoError(t, err)
 	err = ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), h.ID, "1.22.0", sql.NullString{String: "42", Valid: true}, sql.NullBool{Bool: true, Valid: true},
+		context.Background(), h.ID, "1.22.0", sql.NullString{String: "42", Valid: true}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	return orbitKey
@@ -8990,7 +9021,7 @@ func createSession(t *testing.T, uid uint, ds fleet.Datastore) *fleet.Session {
 	require.NoError(t, err)
 
 	sessionKey := base64.StdEncoding.EncodeToString(key)
-	ssn, err := ds.NewSession(context.Background(), uid, sessionKey)
+	ssn, err := ds.NewSession(context.Background(), uid, sessionKey, "", "")
 	require.NoError(t, err)
 
 	return ssn
@@ -11525,3 +11556,474 @@ func (s *integrationTestSuite) TestDebugDB() {
 	s.DoJSON("GET", "/debug/db/innodb-status", nil, http.StatusOK, &responseString)
 	assert.Contains(t, responseString, "INNODB MONITOR OUTPUT")
 }
+
+func (s *integrationTestSuite) TestUserMFA() {
+	t := s.T()
+	ctx := context.Background()
+
+	u := &fleet.User{
+		Name:       "mfa user",
+		Email:      "mfa.user@example.com",
+		GlobalRole: ptr.String(fleet.RoleObserver),
+	}
+	require.NoError(t, u.SetPassword(test.GoodPassword, 10, 10))
+	u, err := s.ds.NewUser(ctx, u)
+	require.NoError(t, err)
+
+	oldToken := s.token
+	t.Cleanup(func() {
+		s.token = oldToken
+	})
+
+	// require MFA enrollment, the user can only use the enrollment endpoints
+	acResp := appConfigResponse{}
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{"mfa_settings": {"required": true}}`), http.StatusOK, &acResp)
+	require.True(t, acResp.MFASettings.Required)
+	t.Cleanup(func() {
+		s.token = s.getTestAdminToken()
+		s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{"mfa_settings": {"required": false}}`), http.StatusOK, &acResp)
+		s.token = oldToken
+	})
+
+	s.token = s.getTestToken(u.Email, test.GoodPassword)
+	var statusResp getMFAStatusResponse
+	s.DoJSON("GET", "/api/latest/fleet/me/mfa", nil, http.StatusOK, &statusResp)
+	require.False(t, statusResp.MFA.Enabled)
+	require.True(t, statusResp.MFA.EnrollmentRequired)
+	s.Do("GET", "/api/latest/fleet/hosts", nil, http.StatusUnauthorized)
+
+	// enroll an authenticator app
+	var totpResp beginTOTPEnrollmentResponse
+	s.DoJSON("POST", "/api/latest/fleet/me/mfa/totp", nil, http.StatusOK, &totpResp)
+	require.NotEmpty(t, totpResp.TOTP.Secret)
+	var codesResp mfaRecoveryCodesResponse
+	s.DoJSON("POST", "/api/latest/fleet/me/mfa/totp/confirm", confirmTOTPEnrollmentRequest{Code: "000000"}, http.StatusUnprocessableEntity, &codesResp)
+	code, err := mfa.TOTPCode(totpResp.TOTP.Secret, mfa.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	s.DoJSON("POST", "/api/latest/fleet/me/mfa/totp/confirm", confirmTOTPEnrollmentRequest{Code: code}, http.StatusOK, &codesResp)
+	require.Len(t, codesResp.RecoveryCodes, mfa.RecoveryCodeCount)
+	s.Do("GET", "/api/latest/fleet/hosts", nil, http.StatusOK)
+
+	statusResp = getMFAStatusResponse{}
+	s.DoJSON("GET", "/api/latest/fleet/me/mfa", nil, http.StatusOK, &statusResp)
+	require.True(t, statusResp.MFA.Enabled)
+	require.True(t, statusResp.MFA.TOTPEnabled)
+	require.False(t, statusResp.MFA.EnrollmentRequired)
+	require.Equal(t, mfa.RecoveryCodeCount, statusResp.MFA.RecoveryCodesRemaining)
+
+	// the password login now requires a second factor
+	var loginResp loginResponse
+	s.DoJSON("POST", "/api/latest/fleet/login", loginRequest{Email: u.Email, Password: test.GoodPassword}, http.StatusOK, &loginResp)
+	require.True(t, loginResp.MFARequired)
+	require.Empty(t, loginResp.Token)
+	require.Nil(t, loginResp.User)
+	require.NotEmpty(t, loginResp.MFAToken)
+	require.ElementsMatch(t, []fleet.MFAMethod{fleet.MFAMethodTOTP, fleet.MFAMethodRecoveryCode}, loginResp.MFAMethods)
+
+	mfaToken := loginResp.MFAToken
+	loginResp = loginResponse{}
+	s.DoJSON("POST", "/api/latest/fleet/login/mfa", loginMFARequest{fleet.MFALoginPayload{Token: mfaToken, RecoveryCode: codesResp.RecoveryCodes[0]}}, http.StatusOK, &loginResp)
+	require.NotEmpty(t, loginResp.Token)
+	require.Equal(t, u.ID, loginResp.User.ID)
+	require.True(t, loginResp.User.MFAEnabled)
+
+	// the mfa token cannot be reused
+	s.DoJSON("POST", "/api/latest/fleet/login/mfa", loginMFARequest{fleet.MFALoginPayload{Token: mfaToken, RecoveryCode: codesResp.RecoveryCodes[1]}}, http.StatusUnauthorized, &loginResp)
+
+	// an admin resets the MFA of the user
+	s.token = s.getTestAdminToken()
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/users/%d/mfa", u.ID), nil, http.StatusOK)
+	s.lastActivityMatches(fleet.ActivityTypeDisabledUserMFA{}.ActivityName(),
+		fmt.Sprintf(`{"user_id": %d, "user_name": "mfa user", "user_email": "mfa.user@example.com", "method": ""}`, u.ID), 0)
+	u, err = s.ds.UserByID(ctx, u.ID)
+	require.NoError(t, err)
+	require.False(t, u.MFAEnabled)
+}
+
+func (s *integrationTestSuite) TestSecretVariables() {
+	t := s.T()
+	ctx := context.Background()
+
+	// invalid names
+	var createResp createSecretVariableResponse
+	s.DoJSON("POST", "/api/latest/fleet/secret_variables", fleet.SecretVariablePayload{Name: "FLEET_SECRET_X", Value: "v"}, http.StatusUnprocessableEntity, &createResp)
+	s.DoJSON("POST", "/api/latest/fleet/secret_variables", fleet.SecretVariablePayload{Name: "X-Y", Value: "v"}, http.StatusUnprocessableEntity, &createResp)
+
+	s.DoJSON("POST", "/api/latest/fleet/secret_variables", fleet.SecretVariablePayload{Name: "INTEG_TOKEN", Value: "t0ps3cr3t"}, http.StatusOK, &createResp)
+	require.NotNil(t, createResp.SecretVariable)
+	require.Equal(t, "INTEG_TOKEN", createResp.SecretVariable.Name)
+	secretID := createResp.SecretVariable.ID
+	s.lastActivityMatches(fleet.ActivityTypeCreatedSecretVariable{}.ActivityName(), `{"secret_name": "INTEG_TOKEN"}`, 0)
+
+	// duplicate name
+	s.DoJSON("POST", "/api/latest/fleet/secret_variables", fleet.SecretVariablePayload{Name: "INTEG_TOKEN", Value: "other"}, http.StatusConflict, &createResp)
+
+	// the value is never returned
+	res := s.Do("GET", "/api/latest/fleet/secret_variables", nil, http.StatusOK)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "INTEG_TOKEN")
+	require.NotContains(t, string(body), "t0ps3cr3t")
+
+	// gitops-style upsert
+	s.Do("PUT", "/api/latest/fleet/spec/secret_variables", map[string]any{
+		"secrets": []fleet.SecretVariablePayload{{Name: "INTEG_URL", Value: "https://example.com"}},
+	}, http.StatusOK)
+	s.lastActivityMatches(fleet.ActivityTypeEditedSecretVariables{}.ActivityName(), `{"secret_names": ["INTEG_URL"]}`, 0)
+	var listResp listSecretVariablesResponse
+	s.DoJSON("GET", "/api/latest/fleet/secret_variables", nil, http.StatusOK, &listResp)
+	names := make([]string, 0, len(listResp.SecretVariables))
+	for _, sv := range listResp.SecretVariables {
+		names = append(names, sv.Name)
+	}
+	require.Contains(t, names, "INTEG_TOKEN")
+	require.Contains(t, names, "INTEG_URL")
+
+	// a script referencing an unknown secret variable is rejected
+	host := createOrbitEnrolledHost(t, "linux", "secret_variables", s.ds)
+	require.NoError(t, s.ds.MarkHostsSeen(ctx, []uint{host.ID}, time.Now()))
+	var runResp runScriptResponse
+	res = s.Do("POST", "/api/latest/fleet/scripts/run", fleet.HostScriptRequestPayload{HostID: host.ID, ScriptContents: "echo $FLEET_SECRET_INTEG_NOPE"}, http.StatusUnprocessableEntity)
+	require.Contains(t, extractServerErrorText(res.Body), "FLEET_SECRET_INTEG_NOPE")
+
+	// the host receives the expanded script, the stored script keeps the reference
+	s.DoJSON("POST", "/api/latest/fleet/scripts/run", fleet.HostScriptRequestPayload{HostID: host.ID, ScriptContents: `curl -H "X: $FLEET_SECRET_INTEG_TOKEN" ${FLEET_SECRET_INTEG_URL}`}, http.StatusAccepted, &runResp)
+	var orbitScriptResp orbitGetScriptResponse
+	s.DoJSON("POST", "/api/fleet/orbit/scripts/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *host.OrbitNodeKey, runResp.ExecutionID)),
+		http.StatusOK, &orbitScriptResp)
+	require.Equal(t, `curl -H "X: t0ps3cr3t" https://example.com`, orbitScriptResp.ScriptContents)
+
+	// the secret values are redacted from the output
+	var orbitPostScriptResp orbitPostScriptResultResponse
+	s.DoJSON("POST", "/api/fleet/orbit/scripts/result",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q, "exit_code": 0, "output": "sent t0ps3cr3t"}`, *host.OrbitNodeKey, runResp.ExecutionID)),
+		http.StatusOK, &orbitPostScriptResp)
+	var scriptResultResp getScriptResultResponse
+	s.DoJSON("GET", "/api/latest/fleet/scripts/results/"+runResp.ExecutionID, nil, http.StatusOK, &scriptResultResp)
+	require.Equal(t, `curl -H "X: $FLEET_SECRET_INTEG_TOKEN" ${FLEET_SECRET_INTEG_URL}`, scriptResultResp.ScriptContents)
+	require.Equal(t, "sent "+fleet.MaskedPassword, scriptResultResp.Output)
+
+	// delete
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/secret_variables/%d", secretID), nil, http.StatusOK)
+	s.lastActivityMatches(fleet.ActivityTypeDeletedSecretVariable{}.ActivityName(), `{"secret_name": "INTEG_TOKEN"}`, 0)
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/secret_variables/%d", secretID), nil, http.StatusNotFound)
+}
+
+func (s *integrationTestSuite) TestTenants() {
+	t := s.T()
+	ctx := context.Background()
+
+	// create
+	var tenantResp tenantResponse
+	s.DoJSON("POST", "/api/latest/fleet/tenants", fleet.TenantPayload{}, http.StatusUnprocessableEntity, &tenantResp)
+	tenantResp = tenantResponse{}
+	s.DoJSON("POST", "/api/latest/fleet/tenants", fleet.TenantPayload{Name: ptr.String("integ-acme")}, http.StatusOK, &tenantResp)
+	require.NotNil(t, tenantResp.Tenant)
+	tenantID := tenantResp.Tenant.ID
+	s.lastActivityMatches(fleet.ActivityTypeCreatedTenant{}.ActivityName(), fmt.Sprintf(`{"tenant_id": %d, "tenant_name": "integ-acme"}`, tenantID), 0)
+	s.Do("POST", "/api/latest/fleet/tenants", fleet.TenantPayload{Name: ptr.String("integ-acme")}, http.StatusConflict)
+
+	// modify, list and get
+	tenantResp = tenantResponse{}
+	s.DoJSON("PATCH", fmt.Sprintf("/api/latest/fleet/tenants/%d", tenantID), fleet.TenantPayload{
+		Config: &fleet.TenantConfig{OrgInfo: &fleet.OrgInfo{OrgName: "Acme Inc."}},
+	}, http.StatusOK, &tenantResp)
+	require.Equal(t, "integ-acme", tenantResp.Tenant.Name)
+	require.Equal(t, "Acme Inc.", tenantResp.Tenant.Config.OrgInfo.OrgName)
+	var listResp listTenantsResponse
+	s.DoJSON("GET", "/api/latest/fleet/tenants", nil, http.StatusOK, &listResp)
+	require.Len(t, listResp.Tenants, 1)
+	require.Equal(t, tenantID, listResp.Tenants[0].ID)
+	s.Do("GET", fmt.Sprintf("/api/latest/fleet/tenants/%d", tenantID+1000), nil, http.StatusNotFound)
+
+	// add a team and a user to the tenant
+	team, err := s.ds.NewTeam(ctx, &fleet.Team{Name: "integ-acme-team"})
+	require.NoError(t, err)
+	user := &fleet.User{
+		Name:       "acme admin",
+		Email:      "acme-admin@example.com",
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	}
+	userRawPwd := test.GoodPassword
+	require.NoError(t, user.SetPassword(userRawPwd, 10, 10))
+	user, err = s.ds.NewUser(ctx, user)
+	require.NoError(t, err)
+
+	tenantResp = tenantResponse{}
+	s.DoJSON("PATCH", fmt.Sprintf("/api/latest/fleet/tenants/%d/teams", tenantID), map[string]any{"team_ids": []uint{team.ID}}, http.StatusOK, &tenantResp)
+	require.Equal(t, 1, tenantResp.Tenant.TeamCount)
+	tenantResp = tenantResponse{}
+	s.DoJSON("PATCH", fmt.Sprintf("/api/latest/fleet/tenants/%d/users", tenantID), map[string]any{"user_ids": []uint{user.ID}}, http.StatusOK, &tenantResp)
+	require.Equal(t, 1, tenantResp.Tenant.UserCount)
+	s.lastActivityMatches(fleet.ActivityTypeEditedTenant{}.ActivityName(), fmt.Sprintf(`{"tenant_id": %d, "tenant_name": "integ-acme"}`, tenantID), 0)
+
+	// the admin of the tenant cannot manage the tenants
+	s.token = s.getTestToken(user.Email, userRawPwd)
+	s.Do("GET", "/api/latest/fleet/tenants", nil, http.StatusForbidden)
+	s.Do("POST", "/api/latest/fleet/tenants", fleet.TenantPayload{Name: ptr.String("integ-other")}, http.StatusForbidden)
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/tenants/%d/users", tenantID), map[string]any{"user_ids": []uint{user.ID}}, http.StatusForbidden)
+	s.token = s.getTestAdminToken()
+
+	// the tenant cannot be deleted while it has teams or users
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/tenants/%d", tenantID), nil, http.StatusBadRequest)
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/tenants/%d/teams", tenantID), map[string]any{"team_ids": []uint{team.ID}}, http.StatusOK)
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/tenants/%d/users", tenantID), map[string]any{"user_ids": []uint{user.ID}}, http.StatusOK)
+	s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/tenants/%d", tenantID), nil, http.StatusOK)
+	s.lastActivityMatches(fleet.ActivityTypeDeletedTenant{}.ActivityName(), fmt.Sprintf(`{"tenant_id": %d, "tenant_name": "integ-acme"}`, tenantID), 0)
+	s.Do("GET", fmt.Sprintf("/api/latest/fleet/tenants/%d", tenantID), nil, http.StatusNotFound)
+
+	require.NoError(t, s.ds.DeleteTeam(ctx, team.ID))
+	require.NoError(t, s.ds.DeleteUser(ctx, user.ID))
+}
+
+func (s *integrationTestSuite) TestHostLogCollections() {
+	t := s.T()
+
+	host := createOrbitEnrolledHost(t, "darwin", "log_collections", s.ds)
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	// invalid requests
+	res := s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/log_collections", host.ID), fleet.HostLogCollectionPayload{
+		Type: fleet.HostLogCollectionWindowsEventLog, Channels: []string{"System"}, StartTime: start,
+	}, http.StatusUnprocessableEntity)
+	require.Contains(t, extractServerErrorText(res.Body), "only be collected from Windows hosts")
+	s.Do("POST", "/api/latest/fleet/hosts/999999/log_collections", fleet.HostLogCollectionPayload{
+		Type: fleet.HostLogCollectionMacOSUnifiedLog, Predicate: "true", StartTime: start,
+	}, http.StatusNotFound)
+
+	var reqResp requestHostLogCollectionResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/log_collections", host.ID), fleet.HostLogCollectionPayload{
+		Type: fleet.HostLogCollectionMacOSUnifiedLog, Predicate: `process == "sshd"`, StartTime: start,
+	}, http.StatusAccepted, &reqResp)
+	require.NotNil(t, reqResp.LogCollection)
+	execID := reqResp.LogCollection.ExecutionID
+	require.Equal(t, fleet.HostLogCollectionStatusPending, reqResp.LogCollection.Status)
+	s.lastActivityMatches(fleet.ActivityTypeRequestedHostLogCollection{}.ActivityName(),
+		fmt.Sprintf(`{"host_id": %d, "host_display_name": %q, "execution_id": %q, "log_type": "macos_unified_log", "start_time": %q, "end_time": %q}`,
+			host.ID, host.DisplayName(), execID, start.Format(time.RFC3339), reqResp.LogCollection.EndTime.Format(time.RFC3339)), 0)
+
+	// the logs cannot be downloaded before they are uploaded
+	s.Do("GET", "/api/latest/fleet/log_collections/"+execID, nil, http.StatusBadRequest, "alt", "media")
+
+	// the host is notified of the pending log collection
+	var orbitConfigResp orbitGetConfigResponse
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Equal(t, []string{execID}, orbitConfigResp.Notifications.PendingLogCollectionIDs)
+
+	var orbitReqResp orbitGetLogCollectionResponse
+	s.DoJSON("POST", "/api/fleet/orbit/log_collections/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *host.OrbitNodeKey, execID)),
+		http.StatusOK, &orbitReqResp)
+	require.Equal(t, `process == "sshd"`, orbitReqResp.Predicate)
+	require.Equal(t, start, orbitReqResp.StartTime.UTC())
+
+	// another host cannot get the request
+	otherHost := createOrbitEnrolledHost(t, "darwin", "log_collections_other", s.ds)
+	s.Do("POST", "/api/fleet/orbit/log_collections/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *otherHost.OrbitNodeKey, execID)),
+		http.StatusNotFound)
+
+	s.Do("POST", "/api/fleet/orbit/log_collections/result", map[string]any{
+		"orbit_node_key": *host.OrbitNodeKey,
+		"execution_id":   execID,
+		"data":           []byte("compressed logs"),
+	}, http.StatusOK)
+
+	// no longer pending
+	orbitConfigResp = orbitGetConfigResponse{}
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Empty(t, orbitConfigResp.Notifications.PendingLogCollectionIDs)
+
+	var listResp listHostLogCollectionsResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/latest/fleet/hosts/%d/log_collections", host.ID), nil, http.StatusOK, &listResp)
+	require.Len(t, listResp.LogCollections, 1)
+	require.Equal(t, fleet.HostLogCollectionStatusCompleted, listResp.LogCollections[0].Status)
+	require.EqualValues(t, len("compressed logs"), listResp.LogCollections[0].Size)
+
+	res = s.Do("GET", "/api/latest/fleet/log_collections/"+execID, nil, http.StatusOK, "alt", "media")
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "compressed logs", string(b))
+	require.Equal(t, "application/gzip", res.Header.Get("Content-Type"))
+	require.Contains(t, res.Header.Get("Content-Disposition"), fmt.Sprintf(`filename="macos_unified_log-%s.tar.gz"`, execID))
+
+	s.Do("GET", "/api/latest/fleet/log_collections/no-such-id", nil, http.StatusNotFound)
+}
+
+func (s *integrationTestSuite) TestHostFileRetrievals() {
+	t := s.T()
+
+	host := createOrbitEnrolledHost(t, "linux", "file_retrievals", s.ds)
+
+	// no path is allowed by default
+	res := s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/file_retrievals", host.ID), fleet.HostFileRetrievalPayload{
+		Path: "/var/log/syslog",
+	}, http.StatusForbidden)
+	require.Contains(t, extractServerErrorText(res.Body), fleet.HostFileRetrievalPathNotAllowedErrMsg)
+
+	// allowed paths must be absolute
+	res = s.Do("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+		"file_retrieval": {"allowed_paths": ["var/log/"]}
+	}`), http.StatusUnprocessableEntity)
+	require.Contains(t, extractServerErrorText(res.Body), "is not an absolute path")
+
+	var acResp appConfigResponse
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+		"file_retrieval": {"allowed_paths": ["/var/log/"], "notify_end_user": true}
+	}`), http.StatusOK, &acResp)
+	require.Equal(t, []string{"/var/log/"}, acResp.FileRetrieval.AllowedPaths)
+	t.Cleanup(func() {
+		s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{
+			"file_retrieval": {"allowed_paths": [], "notify_end_user": false}
+		}`), http.StatusOK, &acResp)
+	})
+
+	s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/file_retrievals", host.ID), fleet.HostFileRetrievalPayload{
+		Path: "/etc/shadow",
+	}, http.StatusForbidden)
+	s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/file_retrievals", host.ID), fleet.HostFileRetrievalPayload{
+		Path: "/var/log/../../etc/shadow",
+	}, http.StatusForbidden)
+	s.Do("POST", "/api/latest/fleet/hosts/999999/file_retrievals", fleet.HostFileRetrievalPayload{
+		Path: "/var/log/syslog",
+	}, http.StatusNotFound)
+
+	var reqResp requestHostFileRetrievalResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/file_retrievals", host.ID), fleet.HostFileRetrievalPayload{
+		Path: "/var/log/syslog",
+	}, http.StatusAccepted, &reqResp)
+	require.NotNil(t, reqResp.FileRetrieval)
+	execID := reqResp.FileRetrieval.ExecutionID
+	require.Equal(t, fleet.HostFileRetrievalStatusPending, reqResp.FileRetrieval.Status)
+	require.True(t, reqResp.FileRetrieval.NotifyEndUser)
+	s.lastActivityMatches(fleet.ActivityTypeRequestedHostFileRetrieval{}.ActivityName(),
+		fmt.Sprintf(`{"host_id": %d, "host_display_name": %q, "execution_id": %q, "path": "/var/log/syslog"}`,
+			host.ID, host.DisplayName(), execID), 0)
+
+	// the file cannot be downloaded before it is uploaded
+	s.Do("GET", "/api/latest/fleet/file_retrievals/"+execID, nil, http.StatusBadRequest, "alt", "media")
+
+	// the host is notified of the pending file retrieval
+	var orbitConfigResp orbitGetConfigResponse
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Equal(t, []string{execID}, orbitConfigResp.Notifications.PendingFileRetrievalIDs)
+
+	var orbitReqResp orbitGetFileRetrievalResponse
+	s.DoJSON("POST", "/api/fleet/orbit/file_retrievals/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *host.OrbitNodeKey, execID)),
+		http.StatusOK, &orbitReqResp)
+	require.Equal(t, "/var/log/syslog", orbitReqResp.Path)
+	require.True(t, orbitReqResp.NotifyEndUser)
+	require.Equal(t, []string{"/var/log/"}, orbitReqResp.AllowedPaths)
+
+	// another host cannot get the request
+	otherHost := createOrbitEnrolledHost(t, "linux", "file_retrievals_other", s.ds)
+	s.Do("POST", "/api/fleet/orbit/file_retrievals/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *otherHost.OrbitNodeKey, execID)),
+		http.StatusNotFound)
+
+	s.Do("POST", "/api/fleet/orbit/file_retrievals/result", map[string]any{
+		"orbit_node_key": *host.OrbitNodeKey,
+		"execution_id":   execID,
+		"data":           []byte("syslog content"),
+	}, http.StatusOK)
+
+	// no longer pending
+	orbitConfigResp = orbitGetConfigResponse{}
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Empty(t, orbitConfigResp.Notifications.PendingFileRetrievalIDs)
+
+	var listResp listHostFileRetrievalsResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/latest/fleet/hosts/%d/file_retrievals", host.ID), nil, http.StatusOK, &listResp)
+	require.Len(t, listResp.FileRetrievals, 1)
+	require.Equal(t, fleet.HostFileRetrievalStatusCompleted, listResp.FileRetrievals[0].Status)
+	require.EqualValues(t, len("syslog content"), listResp.FileRetrievals[0].Size)
+
+	res = s.Do("GET", "/api/latest/fleet/file_retrievals/"+execID, nil, http.StatusOK, "alt", "media")
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "syslog content", string(b))
+	require.Contains(t, res.Header.Get("Content-Disposition"), fmt.Sprintf(`filename="%s-syslog"`, execID))
+	s.lastActivityMatches(fleet.ActivityTypeDownloadedHostFile{}.ActivityName(),
+		fmt.Sprintf(`{"host_id": %d, "host_display_name": %q, "execution_id": %q, "path": "/var/log/syslog"}`,
+			host.ID, host.DisplayName(), execID), 0)
+
+	s.Do("GET", "/api/latest/fleet/file_retrievals/no-such-id", nil, http.StatusNotFound)
+}
+
+func (s *integrationTestSuite) TestHostPprofCaptures() {
+	t := s.T()
+
+	host := createOrbitEnrolledHost(t, "linux", "pprof_captures", s.ds)
+
+	res := s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/pprof_captures", host.ID), fleet.HostPprofCapturePayload{
+		Type: "block",
+	}, http.StatusUnprocessableEntity)
+	require.Contains(t, extractServerErrorText(res.Body), "Invalid profile type")
+	s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/pprof_captures", host.ID), fleet.HostPprofCapturePayload{
+		Type:            fleet.HostPprofProfileCPU,
+		DurationSeconds: 3600,
+	}, http.StatusUnprocessableEntity)
+	s.Do("POST", "/api/latest/fleet/hosts/999999/pprof_captures", fleet.HostPprofCapturePayload{
+		Type: fleet.HostPprofProfileHeap,
+	}, http.StatusNotFound)
+
+	var reqResp requestHostPprofCaptureResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/pprof_captures", host.ID), fleet.HostPprofCapturePayload{
+		Type: fleet.HostPprofProfileCPU,
+	}, http.StatusAccepted, &reqResp)
+	require.NotNil(t, reqResp.PprofCapture)
+	execID := reqResp.PprofCapture.ExecutionID
+	require.Equal(t, fleet.HostPprofCaptureStatusPending, reqResp.PprofCapture.Status)
+	require.Equal(t, uint(30), reqResp.PprofCapture.DurationSeconds)
+	s.lastActivityMatches(fleet.ActivityTypeRequestedHostPprofCapture{}.ActivityName(),
+		fmt.Sprintf(`{"host_id": %d, "host_display_name": %q, "execution_id": %q, "profile_type": "cpu", "duration_seconds": 30}`,
+			host.ID, host.DisplayName(), execID), 0)
+
+	// the profile cannot be downloaded before it is uploaded
+	s.Do("GET", "/api/latest/fleet/pprof_captures/"+execID, nil, http.StatusBadRequest, "alt", "media")
+
+	// the host is notified of the pending pprof capture
+	var orbitConfigResp orbitGetConfigResponse
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Equal(t, []string{execID}, orbitConfigResp.Notifications.PendingPprofCaptureIDs)
+
+	var orbitReqResp orbitGetPprofCaptureResponse
+	s.DoJSON("POST", "/api/fleet/orbit/pprof_captures/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *host.OrbitNodeKey, execID)),
+		http.StatusOK, &orbitReqResp)
+	require.Equal(t, fleet.HostPprofProfileCPU, orbitReqResp.Type)
+	require.Equal(t, uint(30), orbitReqResp.DurationSeconds)
+
+	// another host cannot get the request
+	otherHost := createOrbitEnrolledHost(t, "linux", "pprof_captures_other", s.ds)
+	s.Do("POST", "/api/fleet/orbit/pprof_captures/request",
+		json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q, "execution_id": %q}`, *otherHost.OrbitNodeKey, execID)),
+		http.StatusNotFound)
+
+	s.Do("POST", "/api/fleet/orbit/pprof_captures/result", map[string]any{
+		"orbit_node_key": *host.OrbitNodeKey,
+		"execution_id":   execID,
+		"data":           []byte("cpu profile"),
+	}, http.StatusOK)
+
+	// no longer pending
+	orbitConfigResp = orbitGetConfigResponse{}
+	s.DoJSON("POST", "/api/fleet/orbit/config", json.RawMessage(fmt.Sprintf(`{"orbit_node_key": %q}`, *host.OrbitNodeKey)), http.StatusOK, &orbitConfigResp)
+	require.Empty(t, orbitConfigResp.Notifications.PendingPprofCaptureIDs)
+
+	var listResp listHostPprofCapturesResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/latest/fleet/hosts/%d/pprof_captures", host.ID), nil, http.StatusOK, &listResp)
+	require.Len(t, listResp.PprofCaptures, 1)
+	require.Equal(t, fleet.HostPprofCaptureStatusCompleted, listResp.PprofCaptures[0].Status)
+	require.EqualValues(t, len("cpu profile"), listResp.PprofCaptures[0].Size)
+
+	res = s.Do("GET", "/api/latest/fleet/pprof_captures/"+execID, nil, http.StatusOK, "alt", "media")
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "cpu profile", string(b))
+	require.Contains(t, res.Header.Get("Content-Disposition"), fmt.Sprintf(`filename="%s-cpu.pprof"`, execID))
+
+	s.Do("GET", "/api/latest/fleet/pprof_captures/no-such-id", nil, http.StatusNotFound)
+}
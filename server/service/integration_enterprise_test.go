@@ -947,7 +947,7 @@ func (s *integrationEnterpriseTestSuite) TestAvailableTeams() {
 	// test available teams returned by `/me` endpoint
 	key := make([]byte, 64)
 	sessionKey := base64.StdEncoding.EncodeToString(key)
-	_, err = s.ds.NewSession(context.Background(), user.ID, sessionKey)
+	_, err = s.ds.NewSession(context.Background(), user.ID, sessionKey, "", "")
 	require.NoError(t, err)
 	resp := s.DoRawWithHeaders("GET", "/api/latest/fleet/me", []byte(""), http.StatusOK, map[string]string{
 		"Authorization": fmt.Sprintf("Bearer %s", sessionKey),
@@ -5307,7 +5307,7 @@ func (s *integrationEnterpriseTestSuite) TestRunHostScript() {
 	// Disable scripts on the host
 	scriptsEnabled := false
 	err = s.ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: scriptsEnabled, Valid: true},
+		context.Background(), host.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: scriptsEnabled, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	s.DoJSON(
@@ -5321,7 +5321,7 @@ func (s *integrationEnterpriseTestSuite) TestRunHostScript() {
 	// Re-enable scripts on the host
 	scriptsEnabled = true
 	err = s.ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), host.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: scriptsEnabled, Valid: true},
+		context.Background(), host.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: scriptsEnabled, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 
@@ -7537,7 +7537,7 @@ func (s *integrationEnterpriseTestSuite) TestLockUnlockWipeWindowsLinux() {
 
 	// Disable scripts on Linux host
 	err := s.ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), linuxHost.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: false, Valid: true},
+		context.Background(), linuxHost.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: false, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 	// try to lock/unlock/wipe the Linux host. Fails because scripts are not enabled.
@@ -7553,12 +7553,20 @@ func (s *integrationEnterpriseTestSuite) TestLockUnlockWipeWindowsLinux() {
 
 	// Enable scripts on Linux host
 	err = s.ds.SetOrUpdateHostOrbitInfo(
-		context.Background(), linuxHost.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: true, Valid: true},
+		context.Background(), linuxHost.ID, "1.22.0", sql.NullString{}, sql.NullBool{Bool: true, Valid: true}, sql.NullString{},
 	)
 	require.NoError(t, err)
 
+	// lock/wipe must be confirmed with the serial number or confirmation token
+	res = s.DoRaw("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", linuxHost.ID), nil, http.StatusUnprocessableEntity)
+	errMsg = extractServerErrorText(res.Body)
+	require.Contains(t, errMsg, "To lock the host, confirm with the host's serial number or the confirmation token: "+linuxHost.LockWipeConfirmationToken())
+	res = s.DoRaw("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/wipe", linuxHost.ID), jsonMustMarshal(t, wipeHostRequest{Confirmation: "nope"}), http.StatusUnprocessableEntity)
+	errMsg = extractServerErrorText(res.Body)
+	require.Contains(t, errMsg, "To wipe the host, confirm with the host's serial number")
+
 	// try to lock/unlock/wipe the Linux host succeeds, no MDM constraints
-	s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", linuxHost.ID), nil, http.StatusNoContent)
+	s.Do("POST", fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", linuxHost.ID), lockHostRequest{Confirmation: linuxHost.LockWipeConfirmationToken()}, http.StatusNoContent)
 
 	// simulate a successful script result for the lock command
 	status, err := s.ds.GetHostLockWipeStatus(ctx, linuxHost)
@@ -273,10 +273,11 @@ func (s *integrationMDMTestSuite) runDEPEnrollReleaseDeviceTest(t *testing.T, de
 	}
 
 	// expected commands: install fleetd, install bootstrap, install CA, install profiles
-	// (custom one and fleetd configuration) (not expected: account
-	// configuration, since enrollment_reference not set)
-	require.Len(t, cmds, 5)
-	var installProfileCount, installEnterpriseCount, otherCount int
+	// (custom one and fleetd configuration), escrow the Activation Lock bypass
+	// code (not expected: account configuration, since enrollment_reference
+	// not set)
+	require.Len(t, cmds, 6)
+	var installProfileCount, installEnterpriseCount, activationLockCount, otherCount int
 	var profileCustomSeen, profileFleetdSeen, profileFleetCASeen bool
 	for _, cmd := range cmds {
 		switch cmd.Command.RequestType {
@@ -292,12 +293,15 @@ func (s *integrationMDMTestSuite) runDEPEnrollReleaseDeviceTest(t *testing.T, de
 
 		case "InstallEnterpriseApplication":
 			installEnterpriseCount++
+		case "ActivationLockBypassCode":
+			activationLockCount++
 		default:
 			otherCount++
 		}
 	}
 	require.Equal(t, 3, installProfileCount)
 	require.Equal(t, 2, installEnterpriseCount)
+	require.Equal(t, 1, activationLockCount)
 	require.Equal(t, 0, otherCount)
 	require.True(t, profileCustomSeen)
 	require.True(t, profileFleetdSeen)
@@ -456,8 +460,15 @@ func (s *integrationMDMTestSuite) TestDEPProfileAssignment() {
 	}
 
 	checkPendingMacOSSetupAssistantJob := func(expectedTask string, expectedTeamID *uint, expectedSerials []string, expectedJobID uint) {
-		pending, err := s.ds.GetQueuedJobs(context.Background(), 1, time.Time{})
+		jobs, err := s.ds.GetQueuedJobs(context.Background(), 10, time.Time{})
 		require.NoError(t, err)
+		// transferring hosts also queues a host_transfer job, ignore it
+		var pending []*fleet.Job
+		for _, j := range jobs {
+			if j.Name != "host_transfer" {
+				pending = append(pending, j)
+			}
+		}
 		require.Len(t, pending, 1)
 		require.Equal(t, "macos_setup_assistant", pending[0].Name)
 		require.NotNil(t, pending[0].Args)
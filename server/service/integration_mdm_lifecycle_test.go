@@ -56,7 +56,7 @@ func (s *integrationMDMTestSuite) TestTurnOnLifecycleEventsApple() {
 				s.Do(
 					"POST",
 					fmt.Sprintf("/api/latest/fleet/hosts/%d/wipe", host.ID),
-					nil,
+					wipeHostRequest{Confirmation: host.LockWipeConfirmationToken()},
 					http.StatusNoContent,
 				)
 
@@ -76,7 +76,7 @@ func (s *integrationMDMTestSuite) TestTurnOnLifecycleEventsApple() {
 				s.Do(
 					"POST",
 					fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", host.ID),
-					nil,
+					lockHostRequest{Confirmation: host.LockWipeConfirmationToken()},
 					http.StatusNoContent,
 				)
 
@@ -287,7 +287,7 @@ func (s *integrationMDMTestSuite) TestTurnOnLifecycleEventsWindows() {
 				s.Do(
 					"POST",
 					fmt.Sprintf("/api/latest/fleet/hosts/%d/wipe", host.ID),
-					nil,
+					wipeHostRequest{Confirmation: host.LockWipeConfirmationToken()},
 					http.StatusNoContent,
 				)
 
@@ -332,7 +332,7 @@ func (s *integrationMDMTestSuite) TestTurnOnLifecycleEventsWindows() {
 				s.Do(
 					"POST",
 					fmt.Sprintf("/api/latest/fleet/hosts/%d/lock", host.ID),
-					nil,
+					lockHostRequest{Confirmation: host.LockWipeConfirmationToken()},
 					http.StatusNoContent,
 				)
 
@@ -27,7 +27,9 @@ import (
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/google/uuid"
+	"github.com/groob/plist"
 	"github.com/jmoiron/sqlx"
+	micromdm "github.com/micromdm/micromdm/mdm/mdm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mozilla.org/pkcs7"
@@ -4331,3 +4333,56 @@ func (s *integrationMDMTestSuite) TestMDMAppleConfigProfileCRUD() {
 	deleteResp = deleteMDMAppleConfigProfileResponse{}
 	s.DoJSON("DELETE", deletePath, nil, http.StatusBadRequest, &deleteResp)
 }
+
+func (s *integrationMDMTestSuite) TestAppleProfileSecretVariablesCommandResults() {
+	t := s.T()
+
+	var createResp createSecretVariableResponse
+	s.DoJSON("POST", "/api/latest/fleet/secret_variables", fleet.SecretVariablePayload{Name: "INTEG_PROFILE_NAME", Value: "pr0f1le-s3cr3t"}, http.StatusOK, &createResp)
+	require.NotNil(t, createResp.SecretVariable)
+	t.Cleanup(func() {
+		s.Do("POST", "/api/v1/fleet/mdm/apple/profiles/batch", batchSetMDMAppleProfilesRequest{}, http.StatusNoContent)
+		s.Do("DELETE", fmt.Sprintf("/api/latest/fleet/secret_variables/%d", createResp.SecretVariable.ID), nil, http.StatusOK)
+	})
+
+	_, mdmDevice := createHostThenEnrollMDM(s.ds, s.server.URL, t)
+	s.Do("POST", "/api/v1/fleet/mdm/apple/profiles/batch", batchSetMDMAppleProfilesRequest{Profiles: [][]byte{
+		mobileconfigForTest("N-$FLEET_SECRET_INTEG_PROFILE_NAME", "I-secret"),
+	}}, http.StatusNoContent)
+	s.awaitTriggerProfileSchedule(t)
+
+	// the device receives the profile with the secret variable expanded
+	var installCmdUUID string
+	cmd, err := mdmDevice.Idle()
+	require.NoError(t, err)
+	for cmd != nil {
+		if cmd.Command.RequestType == "InstallProfile" {
+			var fullCmd micromdm.CommandPayload
+			require.NoError(t, plist.Unmarshal(cmd.Raw, &fullCmd))
+			p7, err := pkcs7.Parse(fullCmd.Command.InstallProfile.Payload)
+			require.NoError(t, err)
+			if strings.Contains(string(p7.Content), "I-secret") {
+				require.Contains(t, string(p7.Content), "N-pr0f1le-s3cr3t")
+				installCmdUUID = cmd.CommandUUID
+			}
+		}
+		cmd, err = mdmDevice.Acknowledge(cmd.CommandUUID)
+		require.NoError(t, err)
+	}
+	require.NotEmpty(t, installCmdUUID)
+
+	// the value is redacted from the command results
+	var cmdResResp getMDMCommandResultsResponse
+	s.DoJSON("GET", "/api/latest/fleet/commands/results", nil, http.StatusOK, &cmdResResp, "command_uuid", installCmdUUID)
+	var appleCmdResResp getMDMAppleCommandResultsResponse
+	s.DoJSON("GET", "/api/latest/fleet/mdm/apple/commandresults", nil, http.StatusOK, &appleCmdResResp, "command_uuid", installCmdUUID)
+	for _, results := range [][]*fleet.MDMCommandResult{cmdResResp.Results, appleCmdResResp.Results} {
+		require.Len(t, results, 1)
+		var fullCmd micromdm.CommandPayload
+		require.NoError(t, plist.Unmarshal(results[0].Payload, &fullCmd))
+		require.Equal(t, "InstallProfile", fullCmd.Command.RequestType)
+		profile := string(fullCmd.Command.InstallProfile.Payload)
+		require.NotContains(t, profile, "pr0f1le-s3cr3t")
+		require.Contains(t, profile, "N-"+fleet.MaskedPassword)
+	}
+}
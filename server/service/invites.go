@@ -41,7 +41,7 @@ func createInviteEndpoint(ctx context.Context, request interface{}, svc fleet.Se
 }
 
 func (svc *Service) InviteNewUser(ctx context.Context, payload fleet.InvitePayload) (*fleet.Invite, error) {
-	if err := svc.authz.Authorize(ctx, &fleet.Invite{}, fleet.ActionWrite); err != nil {
+	if err := svc.authz.Authorize(ctx, &fleet.Invite{Teams: payload.Teams}, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 
@@ -79,6 +79,7 @@ func (svc *Service) InviteNewUser(ctx context.Context, payload fleet.InvitePaylo
 		Token:      token,
 		GlobalRole: payload.GlobalRole,
 		Teams:      payload.Teams,
+		TenantID:   inviter.TenantID,
 	}
 	if payload.Position != nil {
 		invite.Position = *payload.Position
@@ -95,7 +96,7 @@ func (svc *Service) InviteNewUser(ctx context.Context, payload fleet.InvitePaylo
 		return nil, err
 	}
 
-	config, err := svc.ds.AppConfig(ctx)
+	config, err := svc.appConfigForUser(ctx, inviter)
 	if err != nil {
 		return nil, err
 	}
@@ -108,18 +109,21 @@ func (svc *Service) InviteNewUser(ctx context.Context, payload fleet.InvitePaylo
 	if config.SMTPSettings != nil {
 		smtpSettings = *config.SMTPSettings
 	}
-	inviteEmail := fleet.Email{
-		Subject:      "You are Invited to Fleet",
+	inviteEmail, customTemplate, err := svc.emailWithTemplate(ctx, fleet.EmailTemplateInvite, fleet.Email{
 		To:           []string{invite.Email},
 		ServerURL:    config.ServerSettings.ServerURL,
 		SMTPSettings: smtpSettings,
-		Mailer: &mail.InviteMailer{
-			Invite:    invite,
-			BaseURL:   template.URL(config.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
-			AssetURL:  getAssetURL(),
-			OrgName:   config.OrgInfo.OrgName,
-			InvitedBy: invitedBy,
-		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	inviteEmail.Mailer = &mail.InviteMailer{
+		Invite:         invite,
+		BaseURL:        template.URL(config.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+		AssetURL:       getAssetURL(),
+		OrgName:        config.OrgInfo.OrgName,
+		InvitedBy:      invitedBy,
+		CustomTemplate: customTemplate,
 	}
 
 	err = svc.mailService.SendEmail(inviteEmail)
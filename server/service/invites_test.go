@@ -11,6 +11,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
@@ -29,6 +30,9 @@ func TestInviteNewUserMock(t *testing.T) {
 	ms.NewInviteFunc = func(ctx context.Context, i *fleet.Invite) (*fleet.Invite, error) {
 		return i, nil
 	}
+	ms.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		return nil, newTestNotFoundError()
+	}
 	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error { return nil }}
 
 	svc := validationMiddleware{&Service{
@@ -56,6 +60,60 @@ func TestInviteNewUserMock(t *testing.T) {
 	require.NotNil(t, err, "should err if the user we're inviting already exists")
 }
 
+func TestInviteNewUserTenant(t *testing.T) {
+	ms := new(mock.Store)
+	ms.UserByEmailFunc = mock.UserWithEmailNotFound()
+	ms.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://acme.co"}}, nil
+	}
+	ms.TenantAppConfigFunc = func(ctx context.Context, tenantID uint) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://acme.co"}, OrgInfo: fleet.OrgInfo{OrgName: "Globex"}}, nil
+	}
+	ms.NewInviteFunc = func(ctx context.Context, i *fleet.Invite) (*fleet.Invite, error) {
+		return i, nil
+	}
+	ms.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		return nil, newTestNotFoundError()
+	}
+	var sent fleet.Email
+	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error {
+		sent = e
+		return nil
+	}}
+
+	svc := validationMiddleware{&Service{
+		ds:          ms,
+		config:      config.TestConfig(),
+		mailService: mailer,
+		clock:       clock.NewMockClock(),
+		authz:       authz.Must(),
+	}, ms, nil}
+
+	tenantAdmin := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1}}
+	ctx := test.UserContext(context.Background(), tenantAdmin)
+
+	// the invites of a tenant user join its tenant and are sent with the
+	// tenant's config
+	invite, err := svc.InviteNewUser(ctx, fleet.InvitePayload{
+		Email: ptr.String("user@acme.co"),
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, invite.TenantID)
+	assert.Equal(t, uint(1), *invite.TenantID)
+	assert.True(t, ms.TenantAppConfigFuncInvoked)
+	assert.Equal(t, "Globex", sent.Mailer.(*mail.InviteMailer).OrgName)
+
+	// roles cannot be given on the teams of another tenant
+	_, err = svc.InviteNewUser(ctx, fleet.InvitePayload{
+		Email: ptr.String("other@acme.co"),
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleObserver}},
+	})
+	require.Error(t, err)
+	var forbidden *authz.Forbidden
+	require.ErrorAs(t, err, &forbidden)
+}
+
 func TestUpdateInvite(t *testing.T) {
 	ms := new(mock.Store)
 	ms.InviteFunc = func(ctx context.Context, id uint) (*fleet.Invite, error) {
@@ -159,6 +217,9 @@ func TestInvitesAuth(t *testing.T) {
 	ds.NewInviteFunc = func(ctx context.Context, i *fleet.Invite) (*fleet.Invite, error) {
 		return &fleet.Invite{}, nil
 	}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		return nil, newTestNotFoundError()
+	}
 	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
 		return &fleet.AppConfig{}, nil
 	}
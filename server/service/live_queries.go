@@ -264,7 +264,7 @@ func (svc *Service) RunLiveQueryDeadline(
 				queryString = query
 			}
 
-			campaign, err := svc.NewDistributedQueryCampaign(ctx, queryString, queryIDPtr, fleet.HostTargets{HostIDs: hostIDs})
+			campaign, err := svc.NewDistributedQueryCampaign(ctx, queryString, queryIDPtr, fleet.HostTargets{HostIDs: hostIDs}, nil)
 			if err != nil {
 				level.Error(svc.logger).Log(
 					"msg", "new distributed query campaign",
@@ -379,11 +379,24 @@ func (svc *Service) RunLiveQueryDeadline(
 }
 
 func (svc *Service) GetCampaignReader(ctx context.Context, campaign *fleet.DistributedQueryCampaign) (<-chan interface{}, context.CancelFunc, error) {
+	return svc.getCampaignReader(ctx, campaign, nil)
+}
+
+// getCampaignReader opens the channel from which the results of the campaign
+// are read. If resumeFrom is not nil, the buffered results after that cursor
+// are replayed on the channel first.
+func (svc *Service) getCampaignReader(ctx context.Context, campaign *fleet.DistributedQueryCampaign, resumeFrom *uint64) (<-chan interface{}, context.CancelFunc, error) {
 	// Open the channel from which we will receive incoming query results
 	// (probably from the redis pubsub implementation)
 	cancelCtx, cancelFunc := context.WithCancel(ctx)
 
-	readChan, err := svc.resultStore.ReadChannel(cancelCtx, *campaign)
+	var readChan <-chan interface{}
+	var err error
+	if resumeFrom != nil {
+		readChan, err = svc.resultStore.ReadChannelFromCursor(cancelCtx, *campaign, *resumeFrom)
+	} else {
+		readChan, err = svc.resultStore.ReadChannel(cancelCtx, *campaign)
+	}
 	if err != nil {
 		cancelFunc()
 		return nil, nil, fmt.Errorf("cannot open read channel for campaign %d ", campaign.ID)
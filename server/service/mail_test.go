@@ -77,10 +77,16 @@ func TestMailService(t *testing.T) {
 		invite = i
 		return invite, nil
 	}
+	ds.EmailTemplateFunc = func(ctx context.Context, name fleet.EmailTemplateName) (*fleet.EmailTemplate, error) {
+		return nil, newTestNotFoundError()
+	}
 
 	ds.SaveAppConfigFunc = func(ctx context.Context, info *fleet.AppConfig) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 
 	ds.InviteFunc = func(ctx context.Context, id uint) (*fleet.Invite, error) {
 		return invite, nil
@@ -14,7 +14,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/VividCortex/mysqlerr"
 	"github.com/docker/go-units"
 	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
 	"github.com/fleetdm/fleet/v4/server"
@@ -29,7 +28,7 @@ import (
 	nanomdm "github.com/fleetdm/fleet/v4/server/mdm/nanomdm/mdm"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/go-kit/kit/log/level"
-	"github.com/go-sql-driver/mysql"
+	"github.com/groob/plist"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -555,47 +554,87 @@ func (svc *Service) enqueueAppleMDMCommand(ctx context.Context, rawXMLCmd []byte
 		}
 	}
 
-	if err := svc.mdmAppleCommander.EnqueueCommand(ctx, deviceIDs, string(rawXMLCmd)); err != nil {
-		// if at least one UUID enqueued properly, return success, otherwise return
-		// error
-		var apnsErr *apple_mdm.APNSDeliveryError
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &apnsErr) {
-			if len(apnsErr.FailedUUIDs) < len(deviceIDs) {
-				// some hosts properly received the command, so return success, with the list
-				// of failed uuids.
-				return &fleet.CommandEnqueueResult{
-					CommandUUID: cmd.CommandUUID,
-					RequestType: cmd.Command.RequestType,
-					FailedUUIDs: apnsErr.FailedUUIDs,
-				}, nil
-			}
-			// push failed for all hosts
-			err := fleet.NewBadGatewayError("Apple push notificiation service", err)
-			return nil, ctxerr.Wrap(ctx, err, "enqueue command")
-
-		} else if errors.As(err, &mysqlErr) {
-			// enqueue may fail with a foreign key constraint error 1452 when one of
-			// the hosts provided is not enrolled in nano_enrollments. Detect when
-			// that's the case and add information to the error.
-			if mysqlErr.Number == mysqlerr.ER_NO_REFERENCED_ROW_2 {
-				err := fleet.NewInvalidArgumentError(
-					"device_ids",
-					fmt.Sprintf("at least one of the hosts is not enrolled in MDM or is not an elegible device: %v", err),
-				).WithStatus(http.StatusBadRequest)
-				return nil, ctxerr.Wrap(ctx, err, "enqueue command")
-			}
-		}
+	if err := svc.checkMDMAppleUserEnrollmentCommand(ctx, rawXMLCmd, deviceIDs); err != nil {
+		return nil, err
+	}
+
+	// the command is enqueued in chunks, so it may be enqueued for some hosts
+	// and not for others; return success if at least one host received it.
+	res, err := svc.mdmAppleCommander.EnqueueCommandInChunks(ctx, deviceIDs, string(rawXMLCmd))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "enqueue command")
+	}
+	if len(res.EnqueuedUUIDs) == 0 {
+		err := fleet.NewInvalidArgumentError(
+			"device_ids",
+			fmt.Sprintf("none of the hosts are enrolled in MDM or are eligible devices: %v", res.SkippedUUIDs),
+		).WithStatus(http.StatusBadRequest)
+		return nil, ctxerr.Wrap(ctx, err, "enqueue command")
+	}
 
+	failed := make(map[string]bool, len(res.FailedUUIDs))
+	for _, uuid := range res.FailedUUIDs {
+		failed[uuid] = true
+	}
+	notified := false
+	for _, uuid := range res.EnqueuedUUIDs {
+		if !failed[uuid] {
+			notified = true
+			break
+		}
+	}
+	if !notified {
+		// push failed for all hosts
+		err := fleet.NewBadGatewayError("Apple push notificiation service", errors.New("push notification failed for all hosts"))
 		return nil, ctxerr.Wrap(ctx, err, "enqueue command")
 	}
+
 	return &fleet.CommandEnqueueResult{
-		CommandUUID: cmd.CommandUUID,
-		RequestType: cmd.Command.RequestType,
-		Platform:    "darwin",
+		CommandUUID:   cmd.CommandUUID,
+		RequestType:   cmd.Command.RequestType,
+		EnqueuedUUIDs: res.EnqueuedUUIDs,
+		SkippedUUIDs:  res.SkippedUUIDs,
+		FailedUUIDs:   res.FailedUUIDs,
+		Platform:      "darwin",
 	}, nil
 }
 
+// checkMDMAppleUserEnrollmentCommand returns an error if the command can't be
+// sent to one or more of the devices because they are enrolled via User
+// Enrollment (BYOD), for which Apple prohibits some commands for privacy
+// reasons.
+func (svc *Service) checkMDMAppleUserEnrollmentCommand(ctx context.Context, rawXMLCmd []byte, deviceIDs []string) error {
+	var payload struct {
+		Command struct {
+			RequestType     string
+			PIN             string
+			ManagedAppsOnly bool
+		}
+	}
+	if err := plist.Unmarshal(rawXMLCmd, &payload); err != nil {
+		err = fleet.NewInvalidArgumentError("command", "unable to decode plist command").WithStatus(http.StatusUnsupportedMediaType)
+		return ctxerr.Wrap(ctx, err, "decode plist command payload")
+	}
+
+	restriction := fleet.MDMAppleUserEnrollmentCommandRestriction(
+		strings.TrimSpace(payload.Command.RequestType), payload.Command.PIN, payload.Command.ManagedAppsOnly)
+	if restriction == "" {
+		return nil
+	}
+
+	byodIDs, err := svc.ds.ListNanoMDMUserEnrollmentDeviceIDs(ctx, deviceIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list user enrollment devices")
+	}
+	if len(byodIDs) > 0 {
+		err := fleet.NewInvalidArgumentError("command", fmt.Sprintf(
+			"Can't run the MDM command because one or more hosts are personally-owned and enrolled via User Enrollment (BYOD). %s Hosts: %s",
+			restriction, strings.Join(byodIDs, ", ")))
+		return ctxerr.Wrap(ctx, err, "check user enrollment command restrictions")
+	}
+	return nil
+}
+
 func (svc *Service) enqueueMicrosoftMDMCommand(ctx context.Context, rawXMLCmd []byte, deviceIDs []string) (result *fleet.CommandEnqueueResult, err error) {
 	cmdMsg, err := fleet.ParseWindowsMDMCommand(rawXMLCmd)
 	if err != nil {
@@ -625,9 +664,10 @@ func (svc *Service) enqueueMicrosoftMDMCommand(ctx context.Context, rawXMLCmd []
 	}
 
 	return &fleet.CommandEnqueueResult{
-		CommandUUID: winCmd.CommandUUID,
-		RequestType: winCmd.TargetLocURI,
-		Platform:    "windows",
+		CommandUUID:   winCmd.CommandUUID,
+		RequestType:   winCmd.TargetLocURI,
+		EnqueuedUUIDs: deviceIDs,
+		Platform:      "windows",
 	}, nil
 }
 
@@ -743,6 +783,12 @@ func (svc *Service) GetMDMCommandResults(ctx context.Context, commandUUID string
 			res.Hostname = hostsByUUID[res.HostUUID].Hostname
 		}
 	}
+
+	// the payloads of the commands that install configuration profiles
+	// contain the values of the secret variables the profiles reference.
+	if err := redactCommandResultsSecretVariables(ctx, svc.ds, results); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "redact secret variables from command payloads")
+	}
 	return results, nil
 }
 
@@ -751,7 +797,12 @@ func (svc *Service) GetMDMCommandResults(ctx context.Context, commandUUID string
 ////////////////////////////////////////////////////////////////////////////////
 
 type listMDMCommandsRequest struct {
-	ListOptions fleet.ListOptions `url:"list_options"`
+	ListOptions   fleet.ListOptions `url:"list_options"`
+	Status        string            `query:"status,optional"`
+	RequestType   string            `query:"request_type,optional"`
+	TeamID        *uint             `query:"team_id,optional"`
+	UpdatedAfter  string            `query:"updated_after,optional"`
+	UpdatedBefore string            `query:"updated_before,optional"`
 }
 
 type listMDMCommandsResponse struct {
@@ -763,9 +814,28 @@ func (r listMDMCommandsResponse) error() error { return r.Err }
 
 func listMDMCommandsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*listMDMCommandsRequest)
-	results, err := svc.ListMDMCommands(ctx, &fleet.MDMCommandListOptions{
+	opts := &fleet.MDMCommandListOptions{
 		ListOptions: req.ListOptions,
-	})
+		Status:      fleet.MDMCommandStatusFilter(req.Status),
+		RequestType: req.RequestType,
+		TeamID:      req.TeamID,
+	}
+	if req.UpdatedAfter != "" {
+		t, err := time.Parse(time.RFC3339, req.UpdatedAfter)
+		if err != nil {
+			return listMDMCommandsResponse{Err: fleet.NewInvalidArgumentError("updated_after", "must be a RFC 3339 timestamp")}, nil
+		}
+		opts.UpdatedAfter = &t
+	}
+	if req.UpdatedBefore != "" {
+		t, err := time.Parse(time.RFC3339, req.UpdatedBefore)
+		if err != nil {
+			return listMDMCommandsResponse{Err: fleet.NewInvalidArgumentError("updated_before", "must be a RFC 3339 timestamp")}, nil
+		}
+		opts.UpdatedBefore = &t
+	}
+
+	results, err := svc.ListMDMCommands(ctx, opts)
 	if err != nil {
 		return listMDMCommandsResponse{
 			Err: err,
@@ -783,6 +853,13 @@ func (svc *Service) ListMDMCommands(ctx context.Context, opts *fleet.MDMCommandL
 		return nil, ctxerr.Wrap(ctx, err)
 	}
 
+	if opts.Status != "" && !opts.Status.IsValid() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("status", "must be one of: pending, acknowledged, error"))
+	}
+	if opts.UpdatedAfter != nil && opts.UpdatedBefore != nil && !opts.UpdatedAfter.Before(*opts.UpdatedBefore) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("updated_after", "must be before updated_before"))
+	}
+
 	vc, ok := viewer.FromContext(ctx)
 	if !ok {
 		return nil, fleet.ErrNoContext
@@ -1169,6 +1246,9 @@ func (svc *Service) DeleteMDMWindowsConfigProfile(ctx context.Context, profileUU
 	}); err != nil {
 		return ctxerr.Wrap(ctx, err, "logging activity for delete mdm windows config profile")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return nil
 }
@@ -1190,7 +1270,8 @@ func isAppleDeclarationUUID(profileUUID string) bool {
 type newMDMConfigProfileRequest struct {
 	TeamID  uint
 	Profile *multipart.FileHeader
-	Labels  []string
+	fleet.MDMProfileLabelTargeting
+	Rollout *fleet.MDMProfileRolloutSettings
 }
 
 func (newMDMConfigProfileRequest) DecodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -1226,6 +1307,33 @@ func (newMDMConfigProfileRequest) DecodeRequest(ctx context.Context, r *http.Req
 
 	// add labels
 	decoded.Labels = r.MultipartForm.Value["labels"]
+	decoded.LabelsIncludeAny = r.MultipartForm.Value["labels_include_any"]
+	decoded.LabelsExcludeAny = r.MultipartForm.Value["labels_exclude_any"]
+
+	// add the staged rollout settings, the rollout is enabled by the canary
+	// percentage
+	if val := r.MultipartForm.Value["rollout_canary_percentage"]; len(val) > 0 {
+		var rollout fleet.MDMProfileRolloutSettings
+		for _, field := range []struct {
+			name string
+			dst  *uint
+		}{
+			{"rollout_canary_percentage", &rollout.CanaryPercentage},
+			{"rollout_failure_threshold_percentage", &rollout.FailureThresholdPercentage},
+			{"rollout_timeout_minutes", &rollout.TimeoutMinutes},
+		} {
+			val := r.MultipartForm.Value[field.name]
+			if len(val) < 1 || val[0] == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(val[0], 10, 32)
+			if err != nil {
+				return nil, &fleet.BadRequestError{Message: fmt.Sprintf("failed to decode %s in multipart form: %s", field.name, err.Error())}
+			}
+			*field.dst = uint(n)
+		}
+		decoded.Rollout = &rollout
+	}
 
 	return &decoded, nil
 }
@@ -1253,6 +1361,14 @@ func newMDMConfigProfileEndpoint(ctx context.Context, request interface{}, svc f
 	if isMobileConfig || isJSON {
 		// Then it's an Apple configuration file
 		if isJSON {
+			if len(req.LabelsIncludeAny) > 0 || len(req.LabelsExcludeAny) > 0 {
+				err := svc.NewMDMUnsupportedDeclarationFields(ctx, req.TeamID, "labels_include_any and labels_exclude_any")
+				return &newMDMConfigProfileResponse{Err: err}, nil
+			}
+			if req.Rollout != nil {
+				err := svc.NewMDMUnsupportedDeclarationFields(ctx, req.TeamID, "rollout")
+				return &newMDMConfigProfileResponse{Err: err}, nil
+			}
 			decl, err := svc.NewMDMAppleDeclaration(ctx, req.TeamID, ff, req.Labels, profileName)
 			if err != nil {
 				return &newMDMConfigProfileResponse{Err: err}, nil
@@ -1264,7 +1380,7 @@ func newMDMConfigProfileEndpoint(ctx context.Context, request interface{}, svc f
 
 		}
 
-		cp, err := svc.NewMDMAppleConfigProfile(ctx, req.TeamID, ff, req.Labels)
+		cp, err := svc.NewMDMAppleConfigProfile(ctx, req.TeamID, ff, req.MDMProfileLabelTargeting, req.Rollout)
 		if err != nil {
 			return &newMDMConfigProfileResponse{Err: err}, nil
 		}
@@ -1274,7 +1390,7 @@ func newMDMConfigProfileEndpoint(ctx context.Context, request interface{}, svc f
 	}
 
 	if isWindows := strings.EqualFold(fileExt, ".xml"); isWindows {
-		cp, err := svc.NewMDMWindowsConfigProfile(ctx, req.TeamID, profileName, ff, req.Labels)
+		cp, err := svc.NewMDMWindowsConfigProfile(ctx, req.TeamID, profileName, ff, req.MDMProfileLabelTargeting, req.Rollout)
 		if err != nil {
 			return &newMDMConfigProfileResponse{Err: err}, nil
 		}
@@ -1298,7 +1414,17 @@ func (svc *Service) NewMDMUnsupportedConfigProfile(ctx context.Context, teamID u
 	return &fleet.BadRequestError{Message: "Couldn't add profile. The file should be a .mobileconfig, XML, or JSON file."}
 }
 
-func (svc *Service) NewMDMWindowsConfigProfile(ctx context.Context, teamID uint, profileName string, r io.Reader, labels []string) (*fleet.MDMWindowsConfigProfile, error) {
+func (svc *Service) NewMDMUnsupportedDeclarationFields(ctx context.Context, teamID uint, fields string) error {
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: &teamID}, fleet.ActionWrite); err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+
+	// same as NewMDMUnsupportedConfigProfile, authorization must be checked
+	// before returning the error.
+	return &fleet.BadRequestError{Message: fmt.Sprintf("Couldn't add profile. The %s fields are only supported for .mobileconfig and XML files.", fields)}
+}
+
+func (svc *Service) NewMDMWindowsConfigProfile(ctx context.Context, teamID uint, profileName string, r io.Reader, labels fleet.MDMProfileLabelTargeting, rollout *fleet.MDMProfileRolloutSettings) (*fleet.MDMWindowsConfigProfile, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: &teamID}, fleet.ActionWrite); err != nil {
 		return nil, ctxerr.Wrap(ctx, err)
 	}
@@ -1342,6 +1468,9 @@ func (svc *Service) NewMDMWindowsConfigProfile(ctx context.Context, teamID uint,
 		err := &fleet.BadRequestError{Message: "Couldn't upload. " + msg}
 		return nil, ctxerr.Wrap(ctx, err, "validate profile")
 	}
+	if err := validateSecretVariables(ctx, svc.ds, "profile", string(b)); err != nil {
+		return nil, err
+	}
 
 	labelMap, err := svc.validateProfileLabels(ctx, labels)
 	if err != nil {
@@ -1349,6 +1478,13 @@ func (svc *Service) NewMDMWindowsConfigProfile(ctx context.Context, teamID uint,
 	}
 	cp.Labels = labelMap
 
+	if rollout != nil {
+		if err := rollout.Validate(); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "validating rollout")
+		}
+		cp.Rollout = rollout
+	}
+
 	newCP, err := svc.ds.NewMDMWindowsConfigProfile(ctx, cp)
 	if err != nil {
 		var existsErr existsErrorInterface
@@ -1378,6 +1514,9 @@ func (svc *Service) NewMDMWindowsConfigProfile(ctx context.Context, teamID uint,
 	}); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "logging activity for create mdm windows config profile")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, actTeamID, authz.UserFromContext(ctx)); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return newCP, nil
 }
@@ -1416,17 +1555,31 @@ func (svc *Service) batchValidateProfileLabels(ctx context.Context, labelNames [
 	return profLabels, nil
 }
 
-func (svc *Service) validateProfileLabels(ctx context.Context, labelNames []string) ([]fleet.ConfigurationProfileLabel, error) {
-	labelMap, err := svc.batchValidateProfileLabels(ctx, labelNames)
+func (svc *Service) validateProfileLabels(ctx context.Context, targeting fleet.MDMProfileLabelTargeting) ([]fleet.ConfigurationProfileLabel, error) {
+	if err := targeting.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validating profile label sets")
+	}
+
+	labelMap, err := svc.batchValidateProfileLabels(ctx, targeting.Names())
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "validating profile labels")
 	}
+	return profileLabelsForTargeting(labelMap, targeting), nil
+}
 
-	var profLabels []fleet.ConfigurationProfileLabel
-	for _, label := range labelMap {
-		profLabels = append(profLabels, label)
+// profileLabelsForTargeting returns the labels of labelMap that are part of
+// the targeting of a profile, flagged with the set they belong to.
+func profileLabelsForTargeting(labelMap map[string]fleet.ConfigurationProfileLabel, targeting fleet.MDMProfileLabelTargeting) []fleet.ConfigurationProfileLabel {
+	pick := func(names []string) []fleet.ConfigurationProfileLabel {
+		var labels []fleet.ConfigurationProfileLabel
+		for _, name := range names {
+			if lbl, ok := labelMap[name]; ok {
+				labels = append(labels, lbl)
+			}
+		}
+		return labels
 	}
-	return profLabels, nil
+	return fleet.JoinConfigurationProfileLabels(pick(targeting.Labels), pick(targeting.LabelsIncludeAny), pick(targeting.LabelsExcludeAny))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -1510,7 +1663,7 @@ func (svc *Service) BatchSetMDMProfiles(
 
 	labels := []string{}
 	for _, prof := range profiles {
-		labels = append(labels, prof.Labels...)
+		labels = append(labels, prof.LabelTargeting().Names()...)
 	}
 	labelMap, err := svc.batchValidateProfileLabels(ctx, labels)
 	if err != nil {
@@ -1535,6 +1688,15 @@ func (svc *Service) BatchSetMDMProfiles(
 		return nil
 	}
 
+	// secret variables are only checked when changes are saved, as a dry run
+	// does not create the secret variables that are applied along with the
+	// profiles.
+	for _, prof := range profiles {
+		if err := validateSecretVariables(ctx, svc.ds, prof.Name, string(prof.Contents)); err != nil {
+			return err
+		}
+	}
+
 	if err := svc.ds.BatchSetMDMProfiles(ctx, tmID, appleProfiles, windowsProfiles, appleDecls); err != nil {
 		return ctxerr.Wrap(ctx, err, "setting config profiles")
 	}
@@ -1578,6 +1740,9 @@ func (svc *Service) BatchSetMDMProfiles(
 	}); err != nil {
 		return ctxerr.Wrap(ctx, err, "logging activity for edited macos declarations")
 	}
+	if _, err := svc.ds.NewConfigVersion(ctx, tmID, authz.UserFromContext(ctx)); err != nil {
+		return ctxerr.Wrap(ctx, err, "record config version")
+	}
 
 	return nil
 }
@@ -1714,6 +1879,16 @@ func getAppleProfiles(
 			if err := rawDecl.ValidateUserProvided(); err != nil {
 				return nil, nil, err
 			}
+			if len(prof.LabelsIncludeAny) > 0 || len(prof.LabelsExcludeAny) > 0 {
+				return nil, nil, ctxerr.Wrap(ctx,
+					fleet.NewInvalidArgumentError(prof.Name, "Couldn’t edit custom_settings. labels_include_any and labels_exclude_any are only supported for .mobileconfig and .xml profiles."),
+					"declaration with label sets")
+			}
+			if prof.Rollout != nil {
+				return nil, nil, ctxerr.Wrap(ctx,
+					fleet.NewInvalidArgumentError(prof.Name, "Couldn’t edit custom_settings. rollout is only supported for .mobileconfig and .xml profiles."),
+					"declaration with rollout")
+			}
 
 			mdmDecl := fleet.NewMDMAppleDeclaration(prof.Contents, tmID, prof.Name, rawDecl.Type, rawDecl.Identifier)
 			for _, labelName := range prof.Labels {
@@ -1757,11 +1932,8 @@ func getAppleProfiles(
 				"invalid mobileconfig profile")
 		}
 
-		for _, labelName := range prof.Labels {
-			if lbl, ok := labelMap[labelName]; ok {
-				mdmProf.Labels = append(mdmProf.Labels, lbl)
-			}
-		}
+		mdmProf.Labels = profileLabelsForTargeting(labelMap, prof.LabelTargeting())
+		mdmProf.Rollout = prof.Rollout
 
 		if err := mdmProf.ValidateUserProvided(); err != nil {
 			return nil, nil, ctxerr.Wrap(ctx,
@@ -1828,11 +2000,8 @@ func getWindowsProfiles(
 			Name:   profile.Name,
 			SyncML: profile.Contents,
 		}
-		for _, labelName := range profile.Labels {
-			if lbl, ok := labelMap[labelName]; ok {
-				mdmProf.Labels = append(mdmProf.Labels, lbl)
-			}
-		}
+		mdmProf.Labels = profileLabelsForTargeting(labelMap, profile.LabelTargeting())
+		mdmProf.Rollout = profile.Rollout
 
 		if err := mdmProf.ValidateUserProvided(); err != nil {
 			return nil, ctxerr.Wrap(ctx,
@@ -1865,6 +2034,14 @@ func validateProfiles(profiles []fleet.MDMProfileBatchPayload) error {
 			// TODO(roberto): there's ongoing feedback with Marko about improving this message, as it's too windows specific
 			return fleet.NewInvalidArgumentError("mdm", "Windows configuration profiles can only have <Replace> or <Add> top level elements.")
 		}
+		if err := profile.LabelTargeting().Validate(); err != nil {
+			return fleet.NewInvalidArgumentError(profile.Name, err.Error())
+		}
+		if profile.Rollout != nil {
+			if err := profile.Rollout.Validate(); err != nil {
+				return fleet.NewInvalidArgumentError(profile.Name, err.Error())
+			}
+		}
 	}
 
 	return nil
@@ -1928,6 +2105,71 @@ func (svc *Service) ListMDMConfigProfiles(ctx context.Context, teamID *uint, opt
 	return svc.ds.ListMDMConfigProfiles(ctx, teamID, opt)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// POST /configuration_profiles/preview_targets
+////////////////////////////////////////////////////////////////////////////////
+
+type previewMDMProfileTargetsRequest struct {
+	TeamID           *uint    `json:"team_id"`
+	Platform         string   `json:"platform"`
+	Labels           []string `json:"labels"`
+	LabelsIncludeAny []string `json:"labels_include_any"`
+	LabelsExcludeAny []string `json:"labels_exclude_any"`
+}
+
+type previewMDMProfileTargetsResponse struct {
+	*fleet.MDMProfileTargetsPreview
+	Err error `json:"error,omitempty"`
+}
+
+func (r previewMDMProfileTargetsResponse) error() error { return r.Err }
+
+func previewMDMProfileTargetsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*previewMDMProfileTargetsRequest)
+
+	preview, err := svc.PreviewMDMProfileTargets(ctx, req.TeamID, req.Platform, fleet.MDMProfileLabelTargeting{
+		Labels:           req.Labels,
+		LabelsIncludeAny: req.LabelsIncludeAny,
+		LabelsExcludeAny: req.LabelsExcludeAny,
+	})
+	if err != nil {
+		return previewMDMProfileTargetsResponse{Err: err}, nil
+	}
+	if preview.Hosts == nil {
+		// return empty json array instead of json null
+		preview.Hosts = []fleet.MDMProfileTargetHost{}
+	}
+	return previewMDMProfileTargetsResponse{MDMProfileTargetsPreview: preview}, nil
+}
+
+func (svc *Service) PreviewMDMProfileTargets(ctx context.Context, teamID *uint, platform string, labels fleet.MDMProfileLabelTargeting) (*fleet.MDMProfileTargetsPreview, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	if platform != "darwin" && platform != "windows" {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("platform", `must be one of "darwin" or "windows"`))
+	}
+
+	if teamID != nil && *teamID > 0 {
+		// confirm that team exists
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err)
+		}
+	}
+
+	profLabels, err := svc.validateProfileLabels(ctx, labels)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validating labels")
+	}
+
+	preview, err := svc.ds.PreviewMDMProfileTargets(ctx, teamID, platform, profLabels)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "preview profile targets")
+	}
+	return preview, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Update MDM Disk encryption
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm/backup"
+	"github.com/google/uuid"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Export MDM assets
+////////////////////////////////////////////////////////////////////////////////
+
+type exportMDMAssetsRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+type exportMDMAssetsResponse struct {
+	Err error `json:"error,omitempty"`
+
+	// fields used by hijackRender for the response.
+	bundle []byte
+}
+
+func (r exportMDMAssetsResponse) error() error { return r.Err }
+
+func (r exportMDMAssetsResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(r.bundle)))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment;filename="fleet-mdm-assets-%s.bundle"`, time.Now().UTC().Format("20060102")))
+
+	// OK to just log the error here as writing anything on
+	// `http.ResponseWriter` sets the status code to 200 (and it can't be
+	// changed.) Clients should rely on matching content-length with the
+	// header provided
+	if n, err := w.Write(r.bundle); err != nil {
+		logging.WithExtras(ctx, "err", err, "bytes_copied", n)
+	}
+}
+
+func exportMDMAssetsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*exportMDMAssetsRequest)
+	bundle, err := svc.ExportMDMAssets(ctx, req.Passphrase)
+	if err != nil {
+		return exportMDMAssetsResponse{Err: err}, nil
+	}
+	return exportMDMAssetsResponse{bundle: bundle}, nil
+}
+
+func (svc *Service) ExportMDMAssets(ctx context.Context, passphrase string) ([]byte, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if len(passphrase) < backup.MinPassphraseLength {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("passphrase",
+			fmt.Sprintf("must be at least %d characters", backup.MinPassphraseLength)))
+	}
+
+	bundle := &fleet.MDMAssetsBundle{
+		Version:           fleet.MDMAssetsBundleVersion,
+		ExportedAt:        svc.clock.Now().UTC(),
+		Assets:            []fleet.MDMBundleAsset{},
+		BootstrapPackages: []fleet.MDMBundleBootstrapPackage{},
+	}
+	addAsset := func(name fleet.TenantMDMAssetName, tenant string, value []byte) {
+		bundle.Assets = append(bundle.Assets, fleet.MDMBundleAsset{Name: name, Tenant: tenant, Value: value})
+	}
+
+	// the assets of the server configuration
+	if svc.config.MDM.IsAppleAPNsSet() {
+		_, cert, key, err := svc.config.MDM.AppleAPNs()
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "load APNs certificate")
+		}
+		addAsset(fleet.TenantMDMAssetAPNSCert, "", cert)
+		addAsset(fleet.TenantMDMAssetAPNSKey, "", key)
+	}
+	if svc.config.MDM.IsAppleSCEPSet() {
+		_, cert, key, err := svc.config.MDM.AppleSCEP()
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "load SCEP certificate")
+		}
+		addAsset(fleet.TenantMDMAssetSCEPCert, "", cert)
+		addAsset(fleet.TenantMDMAssetSCEPKey, "", key)
+	}
+	if svc.config.MDM.IsAppleBMSet() {
+		cert, key, token, err := svc.config.MDM.AppleBMAssets()
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "load ABM token")
+		}
+		addAsset(fleet.TenantMDMAssetABMCert, "", cert)
+		addAsset(fleet.TenantMDMAssetABMKey, "", key)
+		addAsset(fleet.TenantMDMAssetABMToken, "", token)
+	}
+
+	// the assets of the tenants
+	tenants, err := svc.ds.ListTenants(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list tenants")
+	}
+	for _, tenant := range tenants {
+		assets, err := svc.ds.GetTenantMDMAssets(ctx, tenant.ID, fleet.TenantMDMAssetNames)
+		if err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "get MDM assets of tenant %s", tenant.Name)
+		}
+		for _, name := range fleet.TenantMDMAssetNames {
+			if value, ok := assets[name]; ok {
+				addAsset(name, tenant.Name, value)
+			}
+		}
+	}
+
+	// the bootstrap packages of "No team" and the teams
+	teams, err := svc.ds.TeamsSummary(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list teams")
+	}
+	teams = append([]*fleet.TeamSummary{{ID: 0}}, teams...)
+	for _, team := range teams {
+		meta, err := svc.ds.GetMDMAppleBootstrapPackageMeta(ctx, team.ID)
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				continue
+			}
+			return nil, ctxerr.Wrap(ctx, err, "get bootstrap package metadata")
+		}
+		pkg, err := svc.ds.GetMDMAppleBootstrapPackageBytes(ctx, meta.Token)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get bootstrap package bytes")
+		}
+		bundle.BootstrapPackages = append(bundle.BootstrapPackages, fleet.MDMBundleBootstrapPackage{
+			Team:   team.Name,
+			Name:   meta.Name,
+			Sha256: meta.Sha256,
+			Bytes:  pkg.Bytes,
+		})
+	}
+
+	sealed, err := backup.Seal(bundle, passphrase)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "encrypt MDM assets bundle")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeExportedMDMAssets{
+		AssetCount:            len(bundle.Assets),
+		BootstrapPackageCount: len(bundle.BootstrapPackages),
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for export MDM assets")
+	}
+	return sealed, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Import MDM assets
+////////////////////////////////////////////////////////////////////////////////
+
+type importMDMAssetsRequest struct {
+	Bundle     *multipart.FileHeader
+	Passphrase string
+	Overwrite  bool
+}
+
+func (importMDMAssetsRequest) DecodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	decoded := importMDMAssetsRequest{}
+	err := r.ParseMultipartForm(512 * units.MiB)
+	if err != nil {
+		return nil, &fleet.BadRequestError{
+			Message:     "failed to parse multipart form",
+			InternalErr: err,
+		}
+	}
+
+	if r.MultipartForm.File["bundle"] == nil {
+		return nil, &fleet.BadRequestError{Message: "bundle multipart field is required"}
+	}
+	decoded.Bundle = r.MultipartForm.File["bundle"][0]
+
+	if val := r.MultipartForm.Value["passphrase"]; len(val) > 0 {
+		decoded.Passphrase = val[0]
+	}
+	if val := r.MultipartForm.Value["overwrite"]; len(val) > 0 {
+		overwrite, err := strconv.ParseBool(val[0])
+		if err != nil {
+			return nil, &fleet.BadRequestError{Message: fmt.Sprintf("failed to decode overwrite in multipart form: %s", err.Error())}
+		}
+		decoded.Overwrite = overwrite
+	}
+	return &decoded, nil
+}
+
+type importMDMAssetsResponse struct {
+	*fleet.MDMAssetsImportResult
+	Err error `json:"error,omitempty"`
+}
+
+func (r importMDMAssetsResponse) error() error { return r.Err }
+
+func importMDMAssetsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*importMDMAssetsRequest)
+	ff, err := req.Bundle.Open()
+	if err != nil {
+		return importMDMAssetsResponse{Err: err}, nil
+	}
+	defer ff.Close()
+	bundle, err := io.ReadAll(ff)
+	if err != nil {
+		return importMDMAssetsResponse{Err: err}, nil
+	}
+
+	result, err := svc.ImportMDMAssets(ctx, bundle, req.Passphrase, req.Overwrite)
+	if err != nil {
+		return importMDMAssetsResponse{Err: err}, nil
+	}
+	return importMDMAssetsResponse{MDMAssetsImportResult: result}, nil
+}
+
+func (svc *Service) ImportMDMAssets(ctx context.Context, data []byte, passphrase string, overwrite bool) (*fleet.MDMAssetsImportResult, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	bundle, err := backup.Open(data, passphrase)
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidBundle) || errors.Is(err, backup.ErrDecrypt) {
+			return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: err.Error(), InternalErr: err})
+		}
+		return nil, ctxerr.Wrap(ctx, err, "decrypt MDM assets bundle")
+	}
+
+	result := &fleet.MDMAssetsImportResult{
+		Restored: []fleet.MDMAssetsImportItem{},
+		Skipped:  []fleet.MDMAssetsImportItem{},
+	}
+	if err := svc.importTenantMDMAssets(ctx, bundle.Assets, overwrite, result); err != nil {
+		return nil, err
+	}
+	if err := svc.importBootstrapPackages(ctx, bundle.BootstrapPackages, overwrite, result); err != nil {
+		return nil, err
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeImportedMDMAssets{
+		RestoredCount: len(result.Restored),
+		SkippedCount:  len(result.Skipped),
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for import MDM assets")
+	}
+	return result, nil
+}
+
+func (svc *Service) importTenantMDMAssets(ctx context.Context, assets []fleet.MDMBundleAsset, overwrite bool, result *fleet.MDMAssetsImportResult) error {
+	tenants, err := svc.ds.ListTenants(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list tenants")
+	}
+	tenantsByName := make(map[string]*fleet.Tenant, len(tenants))
+	for _, tenant := range tenants {
+		tenantsByName[tenant.Name] = tenant
+	}
+
+	// group the assets by tenant, in the order of the bundle
+	var tenantOrder []*fleet.Tenant
+	byTenant := make(map[uint][]fleet.MDMBundleAsset)
+	for _, asset := range assets {
+		item := fleet.MDMAssetsImportItem{Kind: fleet.MDMBundleItemAsset, Name: string(asset.Name), Tenant: asset.Tenant}
+		if asset.Tenant == "" {
+			item.Reason = "set in the server configuration, use fleetctl to extract it"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		tenant, ok := tenantsByName[asset.Tenant]
+		if !ok {
+			item.Reason = "tenant not found"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		if _, ok := byTenant[tenant.ID]; !ok {
+			tenantOrder = append(tenantOrder, tenant)
+		}
+		byTenant[tenant.ID] = append(byTenant[tenant.ID], asset)
+	}
+
+	for _, tenant := range tenantOrder {
+		tenantAssets := byTenant[tenant.ID]
+		var existing map[fleet.TenantMDMAssetName][]byte
+		if !overwrite {
+			names := make([]fleet.TenantMDMAssetName, 0, len(tenantAssets))
+			for _, asset := range tenantAssets {
+				names = append(names, asset.Name)
+			}
+			existing, err = svc.ds.GetTenantMDMAssets(ctx, tenant.ID, names)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "get MDM assets of tenant %s", tenant.Name)
+			}
+		}
+
+		var toSet []fleet.TenantMDMAsset
+		for _, asset := range tenantAssets {
+			item := fleet.MDMAssetsImportItem{Kind: fleet.MDMBundleItemAsset, Name: string(asset.Name), Tenant: asset.Tenant}
+			if _, ok := existing[asset.Name]; ok {
+				item.Reason = "already exists"
+				result.Skipped = append(result.Skipped, item)
+				continue
+			}
+			toSet = append(toSet, fleet.TenantMDMAsset{Name: asset.Name, Value: asset.Value})
+			result.Restored = append(result.Restored, item)
+		}
+		if err := svc.ds.SetTenantMDMAssets(ctx, tenant.ID, toSet); err != nil {
+			return ctxerr.Wrapf(ctx, err, "set MDM assets of tenant %s", tenant.Name)
+		}
+	}
+	return nil
+}
+
+func (svc *Service) importBootstrapPackages(ctx context.Context, pkgs []fleet.MDMBundleBootstrapPackage, overwrite bool, result *fleet.MDMAssetsImportResult) error {
+	for _, pkg := range pkgs {
+		item := fleet.MDMAssetsImportItem{Kind: fleet.MDMBundleItemBootstrapPackage, Name: pkg.Name, Team: pkg.Team}
+		if !license.IsPremium(ctx) {
+			item.Reason = "requires Fleet Premium"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+
+		var teamID uint
+		if pkg.Team != "" {
+			team, err := svc.ds.TeamByName(ctx, pkg.Team)
+			if err != nil {
+				if fleet.IsNotFound(err) {
+					item.Reason = "team not found"
+					result.Skipped = append(result.Skipped, item)
+					continue
+				}
+				return ctxerr.Wrap(ctx, err, "get team by name")
+			}
+			teamID = team.ID
+		}
+
+		meta, err := svc.ds.GetMDMAppleBootstrapPackageMeta(ctx, teamID)
+		if err != nil && !fleet.IsNotFound(err) {
+			return ctxerr.Wrap(ctx, err, "get bootstrap package metadata")
+		}
+		if meta != nil {
+			if !overwrite || bytes.Equal(meta.Sha256, pkg.Sha256) {
+				item.Reason = "already exists"
+				result.Skipped = append(result.Skipped, item)
+				continue
+			}
+			if err := svc.ds.DeleteMDMAppleBootstrapPackage(ctx, teamID); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete bootstrap package")
+			}
+		}
+
+		if err := svc.ds.InsertMDMAppleBootstrapPackage(ctx, &fleet.MDMAppleBootstrapPackage{
+			TeamID: teamID,
+			Name:   pkg.Name,
+			Token:  uuid.New().String(),
+			Sha256: pkg.Sha256,
+			Bytes:  pkg.Bytes,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert bootstrap package")
+		}
+		result.Restored = append(result.Restored, item)
+	}
+	return nil
+}
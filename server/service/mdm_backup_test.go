@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	apple_mdm "github.com/fleetdm/fleet/v4/server/mdm/apple"
+	"github.com/fleetdm/fleet/v4/server/mdm/backup"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanodep/tokenpki"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportMDMAssets(t *testing.T) {
+	ds := new(mock.Store)
+
+	testCert, testKey, err := apple_mdm.NewSCEPCACertKey()
+	require.NoError(t, err)
+	testCertPEM := tokenpki.PEMCertificate(testCert.Raw)
+	testKeyPEM := tokenpki.PEMRSAPrivateKey(testKey)
+	fleetCfg := config.TestConfig()
+	config.SetTestMDMConfig(t, &fleetCfg, testCertPEM, testKeyPEM, nil, "")
+
+	lic := &fleet.LicenseInfo{Tier: fleet.TierPremium}
+	svc, ctx := newTestServiceWithConfig(t, ds, fleetCfg, nil, nil, &TestServerOpts{License: lic})
+
+	tenantAssets := map[uint]map[fleet.TenantMDMAssetName][]byte{
+		1: {fleet.TenantMDMAssetAPNSCert: []byte("acme cert"), fleet.TenantMDMAssetAPNSKey: []byte("acme key")},
+	}
+	ds.ListTenantsFunc = func(ctx context.Context) ([]*fleet.Tenant, error) {
+		return []*fleet.Tenant{{ID: 1, Name: "acme"}, {ID: 2, Name: "globex"}}, nil
+	}
+	ds.GetTenantMDMAssetsFunc = func(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error) {
+		assets := make(map[fleet.TenantMDMAssetName][]byte)
+		for _, name := range names {
+			if v, ok := tenantAssets[tenantID][name]; ok {
+				assets[name] = v
+			}
+		}
+		return assets, nil
+	}
+	ds.SetTenantMDMAssetsFunc = func(ctx context.Context, tenantID uint, assets []fleet.TenantMDMAsset) error {
+		if tenantAssets[tenantID] == nil {
+			tenantAssets[tenantID] = make(map[fleet.TenantMDMAssetName][]byte)
+		}
+		for _, asset := range assets {
+			tenantAssets[tenantID][asset.Name] = asset.Value
+		}
+		return nil
+	}
+
+	teams := map[string]uint{"Workstations": 1}
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return []*fleet.TeamSummary{{ID: 1, Name: "Workstations"}}, nil
+	}
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		if id, ok := teams[name]; ok {
+			return &fleet.Team{ID: id, Name: name}, nil
+		}
+		return nil, newNotFoundError()
+	}
+	pkgs := map[uint]*fleet.MDMAppleBootstrapPackage{
+		1: {TeamID: 1, Name: "bootstrap.pkg", Token: "tok", Sha256: []byte{1}, Bytes: []byte("pkg")},
+	}
+	ds.GetMDMAppleBootstrapPackageMetaFunc = func(ctx context.Context, teamID uint) (*fleet.MDMAppleBootstrapPackage, error) {
+		if pkg, ok := pkgs[teamID]; ok {
+			return &fleet.MDMAppleBootstrapPackage{TeamID: pkg.TeamID, Name: pkg.Name, Token: pkg.Token, Sha256: pkg.Sha256}, nil
+		}
+		return nil, newNotFoundError()
+	}
+	ds.GetMDMAppleBootstrapPackageBytesFunc = func(ctx context.Context, token string) (*fleet.MDMAppleBootstrapPackage, error) {
+		for _, pkg := range pkgs {
+			if pkg.Token == token {
+				return pkg, nil
+			}
+		}
+		return nil, newNotFoundError()
+	}
+	ds.DeleteMDMAppleBootstrapPackageFunc = func(ctx context.Context, teamID uint) error {
+		delete(pkgs, teamID)
+		return nil
+	}
+	ds.InsertMDMAppleBootstrapPackageFunc = func(ctx context.Context, bp *fleet.MDMAppleBootstrapPackage) error {
+		pkgs[bp.TeamID] = bp
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	const passphrase = "correct horse battery staple"
+
+	// only global admins can export and import the assets
+	maintainerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}})
+	_, err = svc.ExportMDMAssets(maintainerCtx, passphrase)
+	checkAuthErr(t, true, err)
+	_, err = svc.ImportMDMAssets(maintainerCtx, nil, passphrase, false)
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	_, err = svc.ExportMDMAssets(ctx, "short")
+	require.ErrorContains(t, err, "must be at least 12 characters")
+
+	sealed, err := svc.ExportMDMAssets(ctx, passphrase)
+	require.NoError(t, err)
+	require.Equal(t, fleet.ActivityTypeExportedMDMAssets{AssetCount: 9, BootstrapPackageCount: 1}, activities[0])
+
+	bundle, err := backup.Open(sealed, passphrase)
+	require.NoError(t, err)
+	require.Len(t, bundle.Assets, 9)
+	require.Equal(t, fleet.MDMBundleAsset{Name: fleet.TenantMDMAssetAPNSCert, Value: testCertPEM}, bundle.Assets[0])
+	require.Equal(t, fleet.MDMBundleAsset{Name: fleet.TenantMDMAssetABMToken, Value: []byte("whatever-will-not-be-accessed")}, bundle.Assets[6])
+	require.Equal(t, fleet.MDMBundleAsset{Name: fleet.TenantMDMAssetAPNSCert, Tenant: "acme", Value: []byte("acme cert")}, bundle.Assets[7])
+	require.Equal(t, []fleet.MDMBundleBootstrapPackage{{Team: "Workstations", Name: "bootstrap.pkg", Sha256: []byte{1}, Bytes: []byte("pkg")}}, bundle.BootstrapPackages)
+
+	_, err = svc.ImportMDMAssets(ctx, sealed, "wrong passphrase!", false)
+	var badReqErr *fleet.BadRequestError
+	require.ErrorAs(t, err, &badReqErr)
+	require.ErrorContains(t, err, "check the passphrase")
+
+	// import the bundle in another server, where the team and a tenant exist
+	// with different ids and the tenant already has a key.
+	tenantAssets = map[uint]map[fleet.TenantMDMAssetName][]byte{
+		3: {fleet.TenantMDMAssetAPNSKey: []byte("other key")},
+	}
+	ds.ListTenantsFunc = func(ctx context.Context) ([]*fleet.Tenant, error) {
+		return []*fleet.Tenant{{ID: 3, Name: "acme"}}, nil
+	}
+	teams = map[string]uint{"Workstations": 5}
+	pkgs = map[uint]*fleet.MDMAppleBootstrapPackage{}
+	activities = nil
+
+	result, err := svc.ImportMDMAssets(ctx, sealed, passphrase, false)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.MDMAssetsImportItem{
+		{Kind: fleet.MDMBundleItemAsset, Name: "apns_cert", Tenant: "acme"},
+		{Kind: fleet.MDMBundleItemBootstrapPackage, Name: "bootstrap.pkg", Team: "Workstations"},
+	}, result.Restored)
+	require.Len(t, result.Skipped, 8)
+	require.Equal(t, "set in the server configuration, use fleetctl to extract it", result.Skipped[0].Reason)
+	require.Equal(t, fleet.MDMAssetsImportItem{Kind: fleet.MDMBundleItemAsset, Name: "apns_key", Tenant: "acme", Reason: "already exists"}, result.Skipped[7])
+	require.Equal(t, []byte("acme cert"), tenantAssets[3][fleet.TenantMDMAssetAPNSCert])
+	require.Equal(t, []byte("other key"), tenantAssets[3][fleet.TenantMDMAssetAPNSKey])
+	require.Equal(t, []byte("pkg"), pkgs[5].Bytes)
+	require.Equal(t, fleet.ActivityTypeImportedMDMAssets{RestoredCount: 2, SkippedCount: 8}, activities[0])
+
+	// with overwrite, the existing assets are replaced, but not the identical
+	// bootstrap package
+	ds.DeleteMDMAppleBootstrapPackageFuncInvoked = false
+	result, err = svc.ImportMDMAssets(ctx, sealed, passphrase, true)
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 2)
+	require.Equal(t, []byte("acme key"), tenantAssets[3][fleet.TenantMDMAssetAPNSKey])
+	require.Equal(t, fleet.MDMAssetsImportItem{Kind: fleet.MDMBundleItemBootstrapPackage, Name: "bootstrap.pkg", Team: "Workstations", Reason: "already exists"}, result.Skipped[len(result.Skipped)-1])
+	require.False(t, ds.DeleteMDMAppleBootstrapPackageFuncInvoked)
+
+	pkgs[5].Sha256 = []byte{2}
+	result, err = svc.ImportMDMAssets(ctx, sealed, passphrase, true)
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 3)
+	require.True(t, ds.DeleteMDMAppleBootstrapPackageFuncInvoked)
+	require.Equal(t, []byte{1}, pkgs[5].Sha256)
+
+	// the bootstrap packages require Fleet Premium, and their team must exist
+	svc, ctx = newTestServiceWithConfig(t, ds, fleetCfg, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	result, err = svc.ImportMDMAssets(ctx, sealed, passphrase, true)
+	require.NoError(t, err)
+	require.Equal(t, "requires Fleet Premium", result.Skipped[len(result.Skipped)-1].Reason)
+
+	svc, ctx = newTestServiceWithConfig(t, ds, fleetCfg, nil, nil, &TestServerOpts{License: lic})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	teams = map[string]uint{}
+	result, err = svc.ImportMDMAssets(ctx, sealed, passphrase, true)
+	require.NoError(t, err)
+	require.Equal(t, "team not found", result.Skipped[len(result.Skipped)-1].Reason)
+}
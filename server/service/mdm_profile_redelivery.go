@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// POST /configuration_profiles/redelivery_campaigns
+////////////////////////////////////////////////////////////////////////////////
+
+type newMDMProfileRedeliveryCampaignRequest struct {
+	fleet.MDMProfileRedeliveryCampaignPayload
+}
+
+type mdmProfileRedeliveryCampaignResponse struct {
+	Campaign *fleet.MDMProfileRedeliveryCampaign `json:"campaign,omitempty"`
+	Err      error                               `json:"error,omitempty"`
+}
+
+func (r mdmProfileRedeliveryCampaignResponse) error() error { return r.Err }
+
+type newMDMProfileRedeliveryCampaignResponse struct {
+	mdmProfileRedeliveryCampaignResponse
+}
+
+func (r newMDMProfileRedeliveryCampaignResponse) Status() int { return http.StatusCreated }
+
+func newMDMProfileRedeliveryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*newMDMProfileRedeliveryCampaignRequest)
+	campaign, err := svc.NewMDMProfileRedeliveryCampaign(ctx, req.MDMProfileRedeliveryCampaignPayload)
+	if err != nil {
+		return newMDMProfileRedeliveryCampaignResponse{mdmProfileRedeliveryCampaignResponse{Err: err}}, nil
+	}
+	return newMDMProfileRedeliveryCampaignResponse{mdmProfileRedeliveryCampaignResponse{Campaign: campaign}}, nil
+}
+
+func (svc *Service) NewMDMProfileRedeliveryCampaign(ctx context.Context, payload fleet.MDMProfileRedeliveryCampaignPayload) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	// first we perform a basic authz check, the team of the profile is checked
+	// once the profile is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if err := validateProfileRedeliveryRate(payload.HostsPerMinute); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	teamID, profileName, err := svc.getMDMProfileTeamAndName(ctx, payload.ProfileUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: teamID}, fleet.ActionWrite); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "authorizing profile team")
+	}
+
+	var createdByID *uint
+	if user := authz.UserFromContext(ctx); user != nil {
+		createdByID = &user.ID
+	}
+	campaign, err := svc.ds.NewMDMProfileRedeliveryCampaign(ctx, &fleet.MDMProfileRedeliveryCampaign{
+		ProfileUUID:    payload.ProfileUUID,
+		ProfileName:    profileName,
+		TeamID:         teamID,
+		HostsPerMinute: payload.HostsPerMinute,
+		CreatedByID:    createdByID,
+	})
+	if err != nil {
+		var existsErr existsErrorInterface
+		if errors.As(err, &existsErr) {
+			err = fleet.NewInvalidArgumentError("profile_uuid", "A redelivery campaign is already in progress for this profile.").
+				WithStatus(http.StatusConflict)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "create profile redelivery campaign")
+	}
+
+	teamName, err := svc.profileRedeliveryCampaignTeamName(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeCreatedProfileRedeliveryCampaign{
+		CampaignID:     campaign.ID,
+		ProfileName:    campaign.ProfileName,
+		TeamID:         campaign.TeamID,
+		TeamName:       teamName,
+		HostsPerMinute: campaign.HostsPerMinute,
+		TotalHosts:     campaign.TotalHosts,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for profile redelivery campaign")
+	}
+	return campaign, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GET /configuration_profiles/redelivery_campaigns
+////////////////////////////////////////////////////////////////////////////////
+
+type listMDMProfileRedeliveryCampaignsRequest struct {
+	TeamID *uint                                    `query:"team_id,optional"`
+	Status fleet.MDMProfileRedeliveryCampaignStatus `query:"status,optional"`
+}
+
+type listMDMProfileRedeliveryCampaignsResponse struct {
+	Campaigns []*fleet.MDMProfileRedeliveryCampaign `json:"campaigns"`
+	Err       error                                 `json:"error,omitempty"`
+}
+
+func (r listMDMProfileRedeliveryCampaignsResponse) error() error { return r.Err }
+
+func listMDMProfileRedeliveryCampaignsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listMDMProfileRedeliveryCampaignsRequest)
+	campaigns, err := svc.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{
+		TeamID: req.TeamID,
+		Status: req.Status,
+	})
+	if err != nil {
+		return listMDMProfileRedeliveryCampaignsResponse{Err: err}, nil
+	}
+	return listMDMProfileRedeliveryCampaignsResponse{Campaigns: campaigns}, nil
+}
+
+func (svc *Service) ListMDMProfileRedeliveryCampaigns(ctx context.Context, opts fleet.MDMProfileRedeliveryCampaignListOptions) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+	if opts.TeamID != nil && *opts.TeamID == 0 {
+		// team_id=0 is "No team"
+		opts.TeamID = nil
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: opts.TeamID}, fleet.ActionRead); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	if opts.Status != "" && !opts.Status.IsValid() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("status", fmt.Sprintf("Invalid status %q.", opts.Status)))
+	}
+	return svc.ds.ListMDMProfileRedeliveryCampaigns(ctx, opts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GET /configuration_profiles/redelivery_campaigns/{id}
+////////////////////////////////////////////////////////////////////////////////
+
+type getMDMProfileRedeliveryCampaignRequest struct {
+	ID uint `url:"id"`
+}
+
+func getMDMProfileRedeliveryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getMDMProfileRedeliveryCampaignRequest)
+	campaign, err := svc.GetMDMProfileRedeliveryCampaign(ctx, req.ID)
+	if err != nil {
+		return mdmProfileRedeliveryCampaignResponse{Err: err}, nil
+	}
+	return mdmProfileRedeliveryCampaignResponse{Campaign: campaign}, nil
+}
+
+func (svc *Service) GetMDMProfileRedeliveryCampaign(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	// first we perform a basic authz check
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	campaign, err := svc.ds.MDMProfileRedeliveryCampaign(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get profile redelivery campaign")
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: campaign.TeamID}, fleet.ActionRead); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	return campaign, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PATCH /configuration_profiles/redelivery_campaigns/{id}
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyMDMProfileRedeliveryCampaignRequest struct {
+	ID uint `url:"id"`
+	fleet.MDMProfileRedeliveryCampaignUpdate
+}
+
+func modifyMDMProfileRedeliveryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyMDMProfileRedeliveryCampaignRequest)
+	campaign, err := svc.ModifyMDMProfileRedeliveryCampaign(ctx, req.ID, req.MDMProfileRedeliveryCampaignUpdate)
+	if err != nil {
+		return mdmProfileRedeliveryCampaignResponse{Err: err}, nil
+	}
+	return mdmProfileRedeliveryCampaignResponse{Campaign: campaign}, nil
+}
+
+func (svc *Service) ModifyMDMProfileRedeliveryCampaign(ctx context.Context, id uint, payload fleet.MDMProfileRedeliveryCampaignUpdate) (*fleet.MDMProfileRedeliveryCampaign, error) {
+	// first we perform a basic authz check
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	campaign, err := svc.ds.MDMProfileRedeliveryCampaign(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get profile redelivery campaign")
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: campaign.TeamID}, fleet.ActionWrite); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	if campaign.Status == fleet.MDMProfileRedeliveryCampaignCompleted {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("id", "Couldn't update. The redelivery campaign is completed.").
+			WithStatus(http.StatusConflict))
+	}
+
+	if payload.HostsPerMinute != nil {
+		if err := validateProfileRedeliveryRate(*payload.HostsPerMinute); err != nil {
+			return nil, ctxerr.Wrap(ctx, err)
+		}
+		campaign.HostsPerMinute = *payload.HostsPerMinute
+	}
+	if payload.Status != nil {
+		switch *payload.Status {
+		case fleet.MDMProfileRedeliveryCampaignRunning:
+			if campaign.Status == fleet.MDMProfileRedeliveryCampaignPaused {
+				// the next batch is processed as soon as the campaign resumes
+				campaign.LastBatchAt = nil
+			}
+		case fleet.MDMProfileRedeliveryCampaignPaused:
+		default:
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("status", `Status must be "running" or "paused".`))
+		}
+		campaign.Status = *payload.Status
+	}
+
+	if err := svc.ds.UpdateMDMProfileRedeliveryCampaign(ctx, campaign); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update profile redelivery campaign")
+	}
+
+	teamName, err := svc.profileRedeliveryCampaignTeamName(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedProfileRedeliveryCampaign{
+		CampaignID:     campaign.ID,
+		ProfileName:    campaign.ProfileName,
+		TeamID:         campaign.TeamID,
+		TeamName:       teamName,
+		Status:         campaign.Status,
+		HostsPerMinute: campaign.HostsPerMinute,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for edited profile redelivery campaign")
+	}
+
+	return svc.ds.MDMProfileRedeliveryCampaign(ctx, id)
+}
+
+func validateProfileRedeliveryRate(hostsPerMinute uint) error {
+	if hostsPerMinute == 0 || hostsPerMinute > fleet.MaxMDMProfileRedeliveryHostsPerMinute {
+		return fleet.NewInvalidArgumentError("hosts_per_minute",
+			fmt.Sprintf("Must be between 1 and %d.", fleet.MaxMDMProfileRedeliveryHostsPerMinute))
+	}
+	return nil
+}
+
+// getMDMProfileTeamAndName returns the team (nil for "No team") and name of
+// the configuration profile identified by profileUUID, after checking that
+// MDM is configured for the profile's platform.
+func (svc *Service) getMDMProfileTeamAndName(ctx context.Context, profileUUID string) (*uint, string, error) {
+	var (
+		teamID *uint
+		name   string
+	)
+	switch {
+	case strings.HasPrefix(profileUUID, fleet.MDMAppleProfileUUIDPrefix):
+		if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+			return nil, "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest), "check apple mdm enabled")
+		}
+		prof, err := svc.ds.GetMDMAppleConfigProfile(ctx, profileUUID)
+		if err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "getting apple config profile")
+		}
+		teamID, name = prof.TeamID, prof.Name
+
+	case strings.HasPrefix(profileUUID, fleet.MDMAppleDeclarationUUIDPrefix):
+		if err := svc.VerifyMDMAppleConfigured(ctx); err != nil {
+			return nil, "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", fleet.AppleMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest), "check apple mdm enabled")
+		}
+		decl, err := svc.ds.GetMDMAppleDeclaration(ctx, profileUUID)
+		if err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "getting apple declaration")
+		}
+		teamID, name = decl.TeamID, decl.Name
+
+	case strings.HasPrefix(profileUUID, fleet.MDMWindowsProfileUUIDPrefix):
+		if err := svc.VerifyMDMWindowsConfigured(ctx); err != nil {
+			return nil, "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", fleet.WindowsMDMNotConfiguredMessage).WithStatus(http.StatusBadRequest), "check windows mdm enabled")
+		}
+		prof, err := svc.ds.GetMDMWindowsConfigProfile(ctx, profileUUID)
+		if err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "getting windows config profile")
+		}
+		teamID, name = prof.TeamID, prof.Name
+
+	default:
+		return nil, "", ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("profile_uuid", "Invalid profile UUID prefix.").WithStatus(http.StatusNotFound), "check profile UUID prefix")
+	}
+
+	if teamID != nil && *teamID == 0 {
+		teamID = nil
+	}
+	return teamID, name, nil
+}
+
+func (svc *Service) profileRedeliveryCampaignTeamName(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) (*string, error) {
+	if campaign.TeamID == nil {
+		return nil, nil
+	}
+	team, err := svc.ds.Team(ctx, *campaign.TeamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team of profile redelivery campaign")
+	}
+	return &team.Name, nil
+}
+
+// RedeliverMDMProfileCampaigns processes the next batch of hosts of each
+// running profile redelivery campaign, respecting the rate of the campaigns.
+// It is meant to be called by a cron job every minute.
+func RedeliverMDMProfileCampaigns(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, now time.Time) error {
+	campaigns, err := ds.ListRunningMDMProfileRedeliveryCampaigns(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list running profile redelivery campaigns")
+	}
+
+	for _, campaign := range campaigns {
+		size := campaign.NextBatchSize(now)
+		if size == 0 && campaign.RemainingHosts() > 0 {
+			continue
+		}
+		// a batch of size 0 with no remaining hosts completes the campaign
+		n, err := ds.RedeliverMDMProfileCampaignBatch(ctx, campaign.ID, size, now)
+		if err != nil {
+			return ctxerr.Wrapf(ctx, err, "redeliver profile campaign %d", campaign.ID)
+		}
+		level.Debug(logger).Log("msg", "redelivered profile campaign batch", "campaign_id", campaign.ID,
+			"profile_uuid", campaign.ProfileUUID, "hosts", n)
+	}
+	return nil
+}
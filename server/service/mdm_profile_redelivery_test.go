@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRedeliveryCampaignsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		teamProfUUID   = "a-team"
+		globalProfUUID = "a-global"
+		teamCampaign   = 1
+		globalCampaign = 2
+	)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+	ds.GetMDMAppleConfigProfileFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMAppleConfigProfile, error) {
+		if profileUUID == teamProfUUID {
+			return &fleet.MDMAppleConfigProfile{ProfileUUID: profileUUID, Name: "team", TeamID: ptr.Uint(1)}, nil
+		}
+		return &fleet.MDMAppleConfigProfile{ProfileUUID: profileUUID, Name: "global", TeamID: ptr.Uint(0)}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	ds.NewMDMProfileRedeliveryCampaignFunc = func(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) (*fleet.MDMProfileRedeliveryCampaign, error) {
+		campaign.Status = fleet.MDMProfileRedeliveryCampaignRunning
+		return campaign, nil
+	}
+	ds.MDMProfileRedeliveryCampaignFunc = func(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+		c := &fleet.MDMProfileRedeliveryCampaign{ID: id, HostsPerMinute: 10, Status: fleet.MDMProfileRedeliveryCampaignRunning}
+		if id == teamCampaign {
+			c.TeamID = ptr.Uint(1)
+		}
+		return c, nil
+	}
+	ds.ListMDMProfileRedeliveryCampaignsFunc = func(ctx context.Context, opts fleet.MDMProfileRedeliveryCampaignListOptions) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+		return nil, nil
+	}
+	ds.UpdateMDMProfileRedeliveryCampaignFunc = func(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+		shouldFailGlobalRead  bool
+		shouldFailGlobalWrite bool
+	}{
+		{
+			name: "global admin",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+		},
+		{
+			name: "global maintainer",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+		},
+		{
+			name:                  "global observer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailTeamRead:    true,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team observer, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailTeamRead:    true,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, DOES NOT belong to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailTeamRead:    true,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewMDMProfileRedeliveryCampaign(ctx, fleet.MDMProfileRedeliveryCampaignPayload{ProfileUUID: teamProfUUID, HostsPerMinute: 10})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			_, err = svc.NewMDMProfileRedeliveryCampaign(ctx, fleet.MDMProfileRedeliveryCampaignPayload{ProfileUUID: globalProfUUID, HostsPerMinute: 10})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+
+			_, err = svc.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{TeamID: ptr.Uint(1)})
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+			_, err = svc.ListMDMProfileRedeliveryCampaigns(ctx, fleet.MDMProfileRedeliveryCampaignListOptions{TeamID: ptr.Uint(0)})
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.GetMDMProfileRedeliveryCampaign(ctx, teamCampaign)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+			_, err = svc.GetMDMProfileRedeliveryCampaign(ctx, globalCampaign)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			paused := fleet.MDMProfileRedeliveryCampaignPaused
+			_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, teamCampaign, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &paused})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, globalCampaign, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &paused})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+		})
+	}
+}
+
+func TestModifyMDMProfileRedeliveryCampaign(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	var stored fleet.MDMProfileRedeliveryCampaign
+	ds.MDMProfileRedeliveryCampaignFunc = func(ctx context.Context, id uint) (*fleet.MDMProfileRedeliveryCampaign, error) {
+		c := stored
+		return &c, nil
+	}
+	ds.UpdateMDMProfileRedeliveryCampaignFunc = func(ctx context.Context, campaign *fleet.MDMProfileRedeliveryCampaign) error {
+		stored = *campaign
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	lastBatch := time.Now().Add(-time.Minute)
+	stored = fleet.MDMProfileRedeliveryCampaign{ID: 1, HostsPerMinute: 10, Status: fleet.MDMProfileRedeliveryCampaignRunning, LastBatchAt: &lastBatch}
+
+	// pause and change the rate
+	paused := fleet.MDMProfileRedeliveryCampaignPaused
+	c, err := svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &paused, HostsPerMinute: ptr.Uint(20)})
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignPaused, c.Status)
+	require.EqualValues(t, 20, c.HostsPerMinute)
+	require.NotNil(t, c.LastBatchAt)
+	require.Len(t, activities, 1)
+	require.Equal(t, fleet.ActivityTypeEditedProfileRedeliveryCampaign{
+		CampaignID: 1, Status: paused, HostsPerMinute: 20,
+	}, activities[0])
+
+	// resuming processes the next batch immediately
+	running := fleet.MDMProfileRedeliveryCampaignRunning
+	c, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &running})
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRedeliveryCampaignRunning, c.Status)
+	require.Nil(t, c.LastBatchAt)
+
+	// invalid rate
+	_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{HostsPerMinute: ptr.Uint(0)})
+	require.ErrorContains(t, err, "hosts_per_minute")
+	_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{HostsPerMinute: ptr.Uint(fleet.MaxMDMProfileRedeliveryHostsPerMinute + 1)})
+	require.ErrorContains(t, err, "hosts_per_minute")
+
+	// a campaign cannot be marked as completed by a user
+	completed := fleet.MDMProfileRedeliveryCampaignCompleted
+	_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &completed})
+	require.ErrorContains(t, err, "Status must be")
+
+	// a completed campaign cannot be modified
+	stored.Status = fleet.MDMProfileRedeliveryCampaignCompleted
+	_, err = svc.ModifyMDMProfileRedeliveryCampaign(ctx, 1, fleet.MDMProfileRedeliveryCampaignUpdate{Status: &running})
+	require.ErrorContains(t, err, "The redelivery campaign is completed")
+	require.Len(t, activities, 2)
+}
+
+func TestRedeliverMDMProfileCampaigns(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+	now := time.Now()
+
+	ds.ListRunningMDMProfileRedeliveryCampaignsFunc = func(ctx context.Context) ([]*fleet.MDMProfileRedeliveryCampaign, error) {
+		return []*fleet.MDMProfileRedeliveryCampaign{
+			// first batch
+			{ID: 1, Status: fleet.MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100},
+			// last batch was just processed
+			{ID: 2, Status: fleet.MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 10, LastBatchAt: ptr.Time(now)},
+			// last batch was a minute ago, few hosts remaining
+			{ID: 3, Status: fleet.MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 95, LastBatchAt: ptr.Time(now.Add(-time.Minute))},
+			// no remaining hosts, it gets completed
+			{ID: 4, Status: fleet.MDMProfileRedeliveryCampaignRunning, HostsPerMinute: 10, TotalHosts: 100, DeliveredHosts: 100, LastBatchAt: ptr.Time(now)},
+		}, nil
+	}
+	batches := map[uint]uint{}
+	ds.RedeliverMDMProfileCampaignBatchFunc = func(ctx context.Context, campaignID uint, batchSize uint, ts time.Time) (uint, error) {
+		require.Equal(t, now, ts)
+		batches[campaignID] = batchSize
+		return batchSize, nil
+	}
+
+	err := RedeliverMDMProfileCampaigns(ctx, ds, kitlog.NewNopLogger(), now)
+	require.NoError(t, err)
+	require.Equal(t, map[uint]uint{1: 10, 3: 5, 4: 0}, batches)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// GET /configuration_profiles/{profile_uuid}/rollout
+////////////////////////////////////////////////////////////////////////////////
+
+type getMDMProfileRolloutRequest struct {
+	ProfileUUID string `url:"profile_uuid"`
+}
+
+type mdmProfileRolloutResponse struct {
+	Rollout *fleet.MDMProfileRollout `json:"rollout,omitempty"`
+	Err     error                    `json:"error,omitempty"`
+}
+
+func (r mdmProfileRolloutResponse) error() error { return r.Err }
+
+func getMDMProfileRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getMDMProfileRolloutRequest)
+	rollout, err := svc.GetMDMProfileRollout(ctx, req.ProfileUUID)
+	if err != nil {
+		return mdmProfileRolloutResponse{Err: err}, nil
+	}
+	return mdmProfileRolloutResponse{Rollout: rollout}, nil
+}
+
+func (svc *Service) GetMDMProfileRollout(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error) {
+	// first we perform a basic authz check, the team of the profile is checked
+	// once the profile is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	teamID, _, err := svc.getMDMProfileTeamAndName(ctx, profileUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	rollout, err := svc.ds.GetMDMProfileRollout(ctx, profileUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get profile rollout")
+	}
+	return rollout, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PATCH /configuration_profiles/{profile_uuid}/rollout
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyMDMProfileRolloutRequest struct {
+	ProfileUUID string `url:"profile_uuid"`
+	fleet.MDMProfileRolloutUpdate
+}
+
+func modifyMDMProfileRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyMDMProfileRolloutRequest)
+	rollout, err := svc.ModifyMDMProfileRollout(ctx, req.ProfileUUID, req.MDMProfileRolloutUpdate)
+	if err != nil {
+		return mdmProfileRolloutResponse{Err: err}, nil
+	}
+	return mdmProfileRolloutResponse{Rollout: rollout}, nil
+}
+
+func (svc *Service) ModifyMDMProfileRollout(ctx context.Context, profileUUID string, payload fleet.MDMProfileRolloutUpdate) (*fleet.MDMProfileRollout, error) {
+	// first we perform a basic authz check, the team of the profile is checked
+	// once the profile is loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Team{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	teamID, _, err := svc.getMDMProfileTeamAndName(ctx, profileUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.MDMConfigProfileAuthz{TeamID: teamID}, fleet.ActionWrite); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	var reason string
+	switch payload.Status {
+	case fleet.MDMProfileRolloutCanary:
+		reason = "Restarted by a user."
+	case fleet.MDMProfileRolloutCompleted:
+		reason = "Continued to all targeted hosts by a user."
+	case fleet.MDMProfileRolloutHalted:
+		reason = "Halted by a user."
+	default:
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("status", `Status must be "canary", "completed" or "halted".`))
+	}
+
+	rollout, err := svc.ds.GetMDMProfileRollout(ctx, profileUUID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get profile rollout")
+	}
+	if err := svc.ds.UpdateMDMProfileRolloutStatus(ctx, profileUUID, payload.Status, reason); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update profile rollout status")
+	}
+	rollout.Status, rollout.Reason = payload.Status, reason
+	if err := newProfileRolloutActivity(ctx, svc.ds, authz.UserFromContext(ctx), rollout); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.GetMDMProfileRollout(ctx, profileUUID)
+}
+
+// newProfileRolloutActivity records the change of status of the rollout, user
+// is nil if the change was automatic.
+func newProfileRolloutActivity(ctx context.Context, ds fleet.Datastore, user *fleet.User, rollout *fleet.MDMProfileRollout) error {
+	var teamName *string
+	if rollout.TeamID != nil {
+		team, err := ds.Team(ctx, *rollout.TeamID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get team of profile rollout")
+		}
+		teamName = &team.Name
+	}
+	if err := ds.NewActivity(ctx, user, fleet.ActivityTypeUpdatedProfileRollout{
+		ProfileUUID: rollout.ProfileUUID,
+		ProfileName: rollout.ProfileName,
+		TeamID:      rollout.TeamID,
+		TeamName:    teamName,
+		Status:      rollout.Status,
+		Reason:      rollout.Reason,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for profile rollout")
+	}
+	return nil
+}
+
+// ProcessMDMProfileRollouts evaluates the verification results of the canary
+// hosts of each active profile rollout, and continues the rollout to all the
+// targeted hosts or halts it accordingly. It is meant to be called by a cron
+// job every few minutes.
+func ProcessMDMProfileRollouts(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, now time.Time) error {
+	if err := ds.CleanupMDMProfileRollouts(ctx); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup profile rollouts")
+	}
+
+	rollouts, err := ds.ListActiveMDMProfileRollouts(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list active profile rollouts")
+	}
+
+	for _, rollout := range rollouts {
+		res, err := ds.GetMDMProfileRolloutResults(ctx, rollout.ProfileUUID)
+		if err != nil {
+			return ctxerr.Wrapf(ctx, err, "get results of profile rollout %s", rollout.ProfileUUID)
+		}
+
+		status, reason := rollout.Evaluate(*res, now)
+		if status == rollout.Status {
+			continue
+		}
+		if err := ds.UpdateMDMProfileRolloutStatus(ctx, rollout.ProfileUUID, status, reason); err != nil {
+			return ctxerr.Wrapf(ctx, err, "update status of profile rollout %s", rollout.ProfileUUID)
+		}
+		rollout.Status, rollout.Reason = status, reason
+		if err := newProfileRolloutActivity(ctx, ds, nil, rollout); err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", fmt.Sprintf("profile rollout %s", status), "profile_uuid", rollout.ProfileUUID,
+			"reason", reason)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDMProfileRolloutsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	const (
+		teamProfUUID   = "a-team"
+		globalProfUUID = "a-global"
+	)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}, nil
+	}
+	ds.GetMDMAppleConfigProfileFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMAppleConfigProfile, error) {
+		if profileUUID == teamProfUUID {
+			return &fleet.MDMAppleConfigProfile{ProfileUUID: profileUUID, Name: "team", TeamID: ptr.Uint(1)}, nil
+		}
+		return &fleet.MDMAppleConfigProfile{ProfileUUID: profileUUID, Name: "global", TeamID: ptr.Uint(0)}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	ds.GetMDMProfileRolloutFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error) {
+		r := &fleet.MDMProfileRollout{ProfileUUID: profileUUID, Status: fleet.MDMProfileRolloutCanary}
+		if profileUUID == teamProfUUID {
+			r.TeamID = ptr.Uint(1)
+		}
+		return r, nil
+	}
+	ds.UpdateMDMProfileRolloutStatusFunc = func(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailTeamRead    bool
+		shouldFailTeamWrite   bool
+		shouldFailGlobalRead  bool
+		shouldFailGlobalWrite bool
+	}{
+		{
+			name: "global admin",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+		},
+		{
+			name: "global maintainer",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+		},
+		{
+			name:                  "global observer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailTeamRead:    true,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+		{
+			name:                  "team admin, DOES NOT belong to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailTeamRead:    true,
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalRead:  true,
+			shouldFailGlobalWrite: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.GetMDMProfileRollout(ctx, teamProfUUID)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+			_, err = svc.GetMDMProfileRollout(ctx, globalProfUUID)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			halt := fleet.MDMProfileRolloutUpdate{Status: fleet.MDMProfileRolloutHalted}
+			_, err = svc.ModifyMDMProfileRollout(ctx, teamProfUUID, halt)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			_, err = svc.ModifyMDMProfileRollout(ctx, globalProfUUID, halt)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+		})
+	}
+}
+
+func TestModifyMDMProfileRollout(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
+	}
+	ds.GetMDMWindowsConfigProfileFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMWindowsConfigProfile, error) {
+		return &fleet.MDMWindowsConfigProfile{ProfileUUID: profileUUID, Name: "prof"}, nil
+	}
+	stored := fleet.MDMProfileRollout{ProfileUUID: "w1", ProfileName: "prof", Status: fleet.MDMProfileRolloutHalted}
+	ds.GetMDMProfileRolloutFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMProfileRollout, error) {
+		r := stored
+		return &r, nil
+	}
+	ds.UpdateMDMProfileRolloutStatusFunc = func(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error {
+		require.Equal(t, "w1", profileUUID)
+		stored.Status, stored.Reason = status, reason
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.NotNil(t, user)
+		activities = append(activities, activity)
+		return nil
+	}
+
+	// continue the halted rollout to all the hosts
+	r, err := svc.ModifyMDMProfileRollout(ctx, "w1", fleet.MDMProfileRolloutUpdate{Status: fleet.MDMProfileRolloutCompleted})
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutCompleted, r.Status)
+	require.Len(t, activities, 1)
+	require.Equal(t, fleet.ActivityTypeUpdatedProfileRollout{
+		ProfileUUID: "w1", ProfileName: "prof", Status: fleet.MDMProfileRolloutCompleted, Reason: r.Reason,
+	}, activities[0])
+
+	// restart it
+	r, err = svc.ModifyMDMProfileRollout(ctx, "w1", fleet.MDMProfileRolloutUpdate{Status: fleet.MDMProfileRolloutCanary})
+	require.NoError(t, err)
+	require.Equal(t, fleet.MDMProfileRolloutCanary, r.Status)
+	require.Len(t, activities, 2)
+
+	// invalid status
+	_, err = svc.ModifyMDMProfileRollout(ctx, "w1", fleet.MDMProfileRolloutUpdate{Status: "paused"})
+	require.ErrorContains(t, err, "Status must be")
+	require.Len(t, activities, 2)
+}
+
+func TestProcessMDMProfileRollouts(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+	now := time.Now()
+
+	settings := fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 10, TimeoutMinutes: 60}
+	ds.CleanupMDMProfileRolloutsFunc = func(ctx context.Context) error {
+		return nil
+	}
+	ds.ListActiveMDMProfileRolloutsFunc = func(ctx context.Context) ([]*fleet.MDMProfileRollout, error) {
+		return []*fleet.MDMProfileRollout{
+			{ProfileUUID: "a-pending", MDMProfileRolloutSettings: settings, Status: fleet.MDMProfileRolloutCanary, StartedAt: now},
+			{ProfileUUID: "a-verified", MDMProfileRolloutSettings: settings, Status: fleet.MDMProfileRolloutCanary, StartedAt: now},
+			{ProfileUUID: "w-failed", MDMProfileRolloutSettings: settings, Status: fleet.MDMProfileRolloutCanary, StartedAt: now, TeamID: ptr.Uint(1)},
+		}, nil
+	}
+	ds.GetMDMProfileRolloutResultsFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMProfileRolloutResults, error) {
+		switch profileUUID {
+		case "a-verified":
+			return &fleet.MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 10}, nil
+		case "w-failed":
+			return &fleet.MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 1, FailedHosts: 2}, nil
+		default:
+			return &fleet.MDMProfileRolloutResults{CanaryHosts: 10, VerifiedHosts: 5}, nil
+		}
+	}
+	updated := map[string]fleet.MDMProfileRolloutStatus{}
+	ds.UpdateMDMProfileRolloutStatusFunc = func(ctx context.Context, profileUUID string, status fleet.MDMProfileRolloutStatus, reason string) error {
+		require.NotEmpty(t, reason)
+		updated[profileUUID] = status
+		return nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		activities = append(activities, activity)
+		return nil
+	}
+
+	err := ProcessMDMProfileRollouts(ctx, ds, kitlog.NewNopLogger(), now)
+	require.NoError(t, err)
+	require.True(t, ds.CleanupMDMProfileRolloutsFuncInvoked)
+	require.Equal(t, map[string]fleet.MDMProfileRolloutStatus{
+		"a-verified": fleet.MDMProfileRolloutCompleted,
+		"w-failed":   fleet.MDMProfileRolloutHalted,
+	}, updated)
+	require.Len(t, activities, 2)
+	halted := activities[1].(fleet.ActivityTypeUpdatedProfileRollout)
+	require.Equal(t, "w-failed", halted.ProfileUUID)
+	require.Equal(t, ptr.String("team"), halted.TeamName)
+}
+
+func TestMDMBatchSetProfilesRollout(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}, SkipCreateTestUsers: true})
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			MDM: fleet.MDM{
+				EnabledAndConfigured:        true,
+				WindowsEnabledAndConfigured: true,
+			},
+		}, nil
+	}
+	var gotMac []*fleet.MDMAppleConfigProfile
+	var gotWin []*fleet.MDMWindowsConfigProfile
+	ds.BatchSetMDMProfilesFunc = func(ctx context.Context, tmID *uint, macProfiles []*fleet.MDMAppleConfigProfile, winProfiles []*fleet.MDMWindowsConfigProfile, macDecls []*fleet.MDMAppleDeclaration) error {
+		gotMac, gotWin = macProfiles, winProfiles
+		return nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+
+	t.Run("rollouts are saved", func(t *testing.T) {
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "N1", Contents: mobileconfigForTest("N1", "I1"), Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10, FailureThresholdPercentage: 5}},
+			{Name: "N2", Contents: syncMLForTest("./foo")},
+		}, false, false, false)
+		require.NoError(t, err)
+
+		require.Len(t, gotMac, 1)
+		require.Equal(t, &fleet.MDMProfileRolloutSettings{
+			CanaryPercentage: 10, FailureThresholdPercentage: 5, TimeoutMinutes: fleet.DefaultMDMProfileRolloutTimeoutMinutes,
+		}, gotMac[0].Rollout)
+		require.Len(t, gotWin, 1)
+		require.Nil(t, gotWin[0].Rollout)
+	})
+
+	t.Run("invalid rollout", func(t *testing.T) {
+		ds.BatchSetMDMProfilesFuncInvoked = false
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "N2", Contents: syncMLForTest("./foo"), Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 100}},
+		}, false, false, false)
+		require.ErrorContains(t, err, "rollout.canary_percentage")
+		require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	})
+
+	t.Run("declaration with rollout", func(t *testing.T) {
+		ds.BatchSetMDMProfilesFuncInvoked = false
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "D1", Contents: declarationForTest("D1"), Rollout: &fleet.MDMProfileRolloutSettings{CanaryPercentage: 10}},
+		}, false, false, false)
+		require.ErrorContains(t, err, "rollout is only supported for .mobileconfig and .xml profiles")
+		require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"errors"
+	"fmt"
 	"math/big"
 	"os"
 	"strings"
@@ -456,6 +457,132 @@ func TestRunMDMCommandValidations(t *testing.T) {
 	}
 }
 
+func TestRunMDMCommandAppleUserEnrollment(t *testing.T) {
+	ds := new(mock.Store)
+	svc := &Service{ds: ds}
+	ctx := context.Background()
+
+	byodHosts := map[string]bool{"byod": true}
+	ds.ListNanoMDMUserEnrollmentDeviceIDsFunc = func(ctx context.Context, deviceIDs []string) ([]string, error) {
+		var ids []string
+		for _, id := range deviceIDs {
+			if byodHosts[id] {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	rawCmd := func(requestType, extra string) []byte {
+		return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Command</key>
+    <dict>
+        <key>RequestType</key>
+        <string>%s</string>%s
+    </dict>
+    <key>CommandUUID</key>
+    <string>uuid</string>
+</dict>
+</plist>`, requestType, extra))
+	}
+
+	cases := []struct {
+		desc    string
+		cmd     []byte
+		hosts   []string
+		wantErr string
+	}{
+		{"erase corporate host", rawCmd("EraseDevice", ""), []string{"corp"}, ""},
+		{"erase byod host", rawCmd("EraseDevice", ""), []string{"corp", "byod"}, fleet.MDMAppleUserEnrollmentEraseMessage},
+		{"lock byod host with pin", rawCmd("DeviceLock", "<key>PIN</key><string>123456</string>"), []string{"byod"}, fleet.MDMAppleUserEnrollmentLockPINMessage},
+		{"lock byod host without pin", rawCmd("DeviceLock", ""), []string{"byod"}, ""},
+		{"lock corporate host with pin", rawCmd("DeviceLock", "<key>PIN</key><string>123456</string>"), []string{"corp"}, ""},
+		{"all apps of byod host", rawCmd("InstalledApplicationList", ""), []string{"byod"}, fleet.MDMAppleUserEnrollmentAppListMessage},
+		{"all apps of byod host explicitly", rawCmd("InstalledApplicationList", "<key>ManagedAppsOnly</key><false/>"), []string{"byod"}, fleet.MDMAppleUserEnrollmentAppListMessage},
+		{"managed apps of byod host", rawCmd("InstalledApplicationList", "<key>ManagedAppsOnly</key><true/>"), []string{"byod"}, ""},
+		{"other command on byod host", rawCmd("ProfileList", ""), []string{"byod"}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			ds.ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked = false
+			err := svc.checkMDMAppleUserEnrollmentCommand(ctx, c.cmd, c.hosts)
+			if c.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, "enrolled via User Enrollment (BYOD)")
+			require.ErrorContains(t, err, c.wantErr)
+			require.ErrorContains(t, err, "Hosts: byod")
+			require.True(t, ds.ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked)
+		})
+	}
+
+	// the enrollments are not loaded if the command is allowed on any host
+	ds.ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked = false
+	require.NoError(t, svc.checkMDMAppleUserEnrollmentCommand(ctx, rawCmd("ProfileList", ""), []string{"byod"}))
+	require.False(t, ds.ListNanoMDMUserEnrollmentDeviceIDsFuncInvoked)
+}
+
+func TestListMDMCommandsFilters(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	var gotOpts *fleet.MDMCommandListOptions
+	ds.ListMDMCommandsFunc = func(ctx context.Context, tmFilter fleet.TeamFilter, listOpts *fleet.MDMCommandListOptions) ([]*fleet.MDMCommand, error) {
+		gotOpts = listOpts
+		return []*fleet.MDMCommand{{CommandUUID: "a", RequestType: "InstallProfile", Status: "Error"}}, nil
+	}
+
+	now := time.Now().UTC()
+	cases := []struct {
+		desc    string
+		opts    *fleet.MDMCommandListOptions
+		wantErr string
+	}{
+		{"no filters", &fleet.MDMCommandListOptions{}, ""},
+		{"valid status", &fleet.MDMCommandListOptions{Status: fleet.MDMCommandStatusFilterError}, ""},
+		{"invalid status", &fleet.MDMCommandListOptions{Status: "failed"}, "status must be one of: pending, acknowledged, error"},
+		{
+			"valid time range",
+			&fleet.MDMCommandListOptions{UpdatedAfter: ptr.Time(now.Add(-time.Hour)), UpdatedBefore: &now},
+			"",
+		},
+		{
+			"invalid time range",
+			&fleet.MDMCommandListOptions{UpdatedAfter: &now, UpdatedBefore: ptr.Time(now.Add(-time.Hour))},
+			"updated_after must be before updated_before",
+		},
+		{
+			"all filters",
+			&fleet.MDMCommandListOptions{
+				Status:       fleet.MDMCommandStatusFilterError,
+				RequestType:  "InstallProfile",
+				TeamID:       ptr.Uint(1),
+				UpdatedAfter: ptr.Time(now.Add(-24 * time.Hour)),
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			gotOpts = nil
+			res, err := svc.ListMDMCommands(ctx, c.opts)
+			if c.wantErr != "" {
+				require.ErrorContains(t, err, c.wantErr)
+				require.Nil(t, gotOpts)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			require.Equal(t, c.opts, gotOpts)
+		})
+	}
+}
+
 func TestMDMCommonAuthorization(t *testing.T) {
 	ds := new(mock.Store)
 	license := &fleet.LicenseInfo{Tier: fleet.TierPremium}
@@ -471,6 +598,9 @@ func TestMDMCommonAuthorization(t *testing.T) {
 	ds.GetMDMWindowsBitLockerSummaryFunc = func(ctx context.Context, teamID *uint) (*fleet.MDMWindowsBitLockerSummary, error) {
 		return &fleet.MDMWindowsBitLockerSummary{}, nil
 	}
+	ds.GetMDMLinuxDiskEncryptionSummaryFunc = func(ctx context.Context, teamID *uint) (*fleet.MDMLinuxDiskEncryptionSummary, error) {
+		return &fleet.MDMLinuxDiskEncryptionSummary{}, nil
+	}
 	ds.GetMDMWindowsProfilesSummaryFunc = func(ctx context.Context, teamID *uint) (*fleet.MDMProfilesSummary, error) {
 		return &fleet.MDMProfilesSummary{}, nil
 	}
@@ -720,8 +850,13 @@ func TestGetMDMDiskEncryptionSummary(t *testing.T) {
 		// Use default zeros verifying, action_required, or removing_enforcement
 		return &fleet.MDMWindowsBitLockerSummary{Verified: 7, Failed: 8, Enforcing: 9}, nil
 	}
+	ds.GetMDMLinuxDiskEncryptionSummaryFunc = func(ctx context.Context, teamID *uint) (*fleet.MDMLinuxDiskEncryptionSummary, error) {
+		require.Nil(t, teamID)
+		// verifying and removing_enforcement are not applicable to Linux
+		return &fleet.MDMLinuxDiskEncryptionSummary{Verified: 10, ActionRequired: 11, Failed: 12, Enforcing: 13}, nil
+	}
 
-	// Test that the summary properly combines the results of the two methods
+	// Test that the summary properly combines the results of the three methods
 	des, err := svc.GetMDMDiskEncryptionSummary(ctx, nil)
 	require.NoError(t, err)
 	require.NotNil(t, des)
@@ -729,26 +864,32 @@ func TestGetMDMDiskEncryptionSummary(t *testing.T) {
 		Verified: fleet.MDMPlatformsCounts{
 			MacOS:   1,
 			Windows: 7,
+			Linux:   10,
 		},
 		Verifying: fleet.MDMPlatformsCounts{
 			MacOS:   2,
 			Windows: 0,
+			Linux:   0,
 		},
 		ActionRequired: fleet.MDMPlatformsCounts{
 			MacOS:   3,
 			Windows: 0,
+			Linux:   11,
 		},
 		Failed: fleet.MDMPlatformsCounts{
 			MacOS:   4,
 			Windows: 8,
+			Linux:   12,
 		},
 		Enforcing: fleet.MDMPlatformsCounts{
 			MacOS:   5,
 			Windows: 9,
+			Linux:   13,
 		},
 		RemovingEnforcement: fleet.MDMPlatformsCounts{
 			MacOS:   6,
 			Windows: 0,
+			Linux:   0,
 		},
 	})
 }
@@ -932,6 +1073,9 @@ func TestMDMWindowsConfigProfileAuthz(t *testing.T) {
 	ds.DeleteMDMWindowsConfigProfileFunc = func(ctx context.Context, profileUUID string) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewMDMWindowsConfigProfileFunc = func(ctx context.Context, cp fleet.MDMWindowsConfigProfile) (*fleet.MDMWindowsConfigProfile, error) {
 		return &cp, nil
 	}
@@ -973,11 +1117,11 @@ func TestMDMWindowsConfigProfileAuthz(t *testing.T) {
 			checkShouldFail(t, err, tt.shouldFailTeamRead)
 
 			// test authz create new profile (no team)
-			_, err = svc.NewMDMWindowsConfigProfile(ctx, 0, "prof", strings.NewReader(winProfContent), nil)
+			_, err = svc.NewMDMWindowsConfigProfile(ctx, 0, "prof", strings.NewReader(winProfContent), fleet.MDMProfileLabelTargeting{}, nil)
 			checkShouldFail(t, err, tt.shouldFailGlobalWrite)
 
 			// test authz create new profile (team 1)
-			_, err = svc.NewMDMWindowsConfigProfile(ctx, 1, "prof", strings.NewReader(winProfContent), nil)
+			_, err = svc.NewMDMWindowsConfigProfile(ctx, 1, "prof", strings.NewReader(winProfContent), fleet.MDMProfileLabelTargeting{}, nil)
 			checkShouldFail(t, err, tt.shouldFailTeamWrite)
 
 			// test authz delete config profile (no team)
@@ -1012,6 +1156,9 @@ func TestUploadWindowsMDMConfigProfileValidations(t *testing.T) {
 		cp.ProfileUUID = uuid.New().String()
 		return &cp, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string) error {
 		return nil
 	}
@@ -1059,7 +1206,7 @@ func TestUploadWindowsMDMConfigProfileValidations(t *testing.T) {
 				}, nil
 			}
 			ctx = test.UserContext(ctx, test.UserAdmin)
-			_, err := svc.NewMDMWindowsConfigProfile(ctx, c.tmID, "foo", strings.NewReader(c.profile), nil)
+			_, err := svc.NewMDMWindowsConfigProfile(ctx, c.tmID, "foo", strings.NewReader(c.profile), fleet.MDMProfileLabelTargeting{}, nil)
 			if c.wantErr != "" {
 				require.Error(t, err)
 				require.ErrorContains(t, err, c.wantErr)
@@ -1098,6 +1245,9 @@ func TestMDMBatchSetProfiles(t *testing.T) {
 	ds.BatchSetMDMProfilesFunc = func(ctx context.Context, tmID *uint, macProfiles []*fleet.MDMAppleConfigProfile, winProfiles []*fleet.MDMWindowsConfigProfile, macDecls []*fleet.MDMAppleDeclaration) error {
 		return nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
@@ -1463,6 +1613,156 @@ func TestValidateProfiles(t *testing.T) {
 	}
 }
 
+func TestMDMBatchSetProfilesLabelTargeting(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}, SkipCreateTestUsers: true})
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			MDM: fleet.MDM{
+				EnabledAndConfigured:        true,
+				WindowsEnabledAndConfigured: true,
+			},
+		}, nil
+	}
+	labelIDs := map[string]uint{"a": 1, "b": 2, "c": 3, "d": 4}
+	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string) (map[string]uint, error) {
+		res := make(map[string]uint)
+		for _, name := range names {
+			if id, ok := labelIDs[name]; ok {
+				res[name] = id
+			}
+		}
+		return res, nil
+	}
+	var gotMac []*fleet.MDMAppleConfigProfile
+	var gotWin []*fleet.MDMWindowsConfigProfile
+	ds.BatchSetMDMProfilesFunc = func(ctx context.Context, tmID *uint, macProfiles []*fleet.MDMAppleConfigProfile, winProfiles []*fleet.MDMWindowsConfigProfile, macDecls []*fleet.MDMAppleDeclaration) error {
+		gotMac, gotWin = macProfiles, winProfiles
+		return nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs []uint, teamIDs []uint, profileUUIDs []string, hostUUIDs []string) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+
+	t.Run("labels sets are saved", func(t *testing.T) {
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "N1", Contents: mobileconfigForTest("N1", "I1"), Labels: []string{"a"}, LabelsIncludeAny: []string{"b", "c"}, LabelsExcludeAny: []string{"d"}},
+			{Name: "N2", Contents: syncMLForTest("./foo"), LabelsExcludeAny: []string{"a"}},
+		}, false, false, false)
+		require.NoError(t, err)
+
+		require.Len(t, gotMac, 1)
+		all, anyOf, excl := fleet.SplitConfigurationProfileLabels(gotMac[0].Labels)
+		require.Equal(t, []fleet.ConfigurationProfileLabel{{LabelName: "a", LabelID: 1, RequireAll: true}}, all)
+		require.ElementsMatch(t, []fleet.ConfigurationProfileLabel{{LabelName: "b", LabelID: 2}, {LabelName: "c", LabelID: 3}}, anyOf)
+		require.Equal(t, []fleet.ConfigurationProfileLabel{{LabelName: "d", LabelID: 4, Exclude: true}}, excl)
+
+		require.Len(t, gotWin, 1)
+		require.Equal(t, []fleet.ConfigurationProfileLabel{{LabelName: "a", LabelID: 1, Exclude: true}}, gotWin[0].Labels)
+	})
+
+	t.Run("label in more than one set", func(t *testing.T) {
+		ds.BatchSetMDMProfilesFuncInvoked = false
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "N1", Contents: mobileconfigForTest("N1", "I1"), LabelsIncludeAny: []string{"a"}, LabelsExcludeAny: []string{"a"}},
+		}, false, false, false)
+		require.ErrorContains(t, err, `label "a" cannot be in both labels_include_any and labels_exclude_any`)
+		require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	})
+
+	t.Run("unknown excluded label", func(t *testing.T) {
+		ds.BatchSetMDMProfilesFuncInvoked = false
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "N1", Contents: mobileconfigForTest("N1", "I1"), LabelsExcludeAny: []string{"no-such-label"}},
+		}, false, false, false)
+		require.ErrorContains(t, err, "some or all the labels provided don't exist")
+		require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	})
+
+	t.Run("declaration with label sets", func(t *testing.T) {
+		ds.BatchSetMDMProfilesFuncInvoked = false
+		err := svc.BatchSetMDMProfiles(ctx, nil, nil, []fleet.MDMProfileBatchPayload{
+			{Name: "D1", Contents: declarationForTest("D1"), LabelsIncludeAny: []string{"a"}},
+		}, false, false, false)
+		require.ErrorContains(t, err, "labels_include_any and labels_exclude_any are only supported for .mobileconfig and .xml profiles")
+		require.False(t, ds.BatchSetMDMProfilesFuncInvoked)
+	})
+}
+
+func TestPreviewMDMProfileTargets(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}, SkipCreateTestUsers: true})
+
+	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string) (map[string]uint, error) {
+		res := make(map[string]uint)
+		for _, name := range names {
+			if id, ok := map[string]uint{"a": 1, "b": 2}[name]; ok {
+				res[name] = id
+			}
+		}
+		return res, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, id uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: id, Name: "team"}, nil
+	}
+	var gotLabels []fleet.ConfigurationProfileLabel
+	ds.PreviewMDMProfileTargetsFunc = func(ctx context.Context, teamID *uint, platform string, labels []fleet.ConfigurationProfileLabel) (*fleet.MDMProfileTargetsPreview, error) {
+		gotLabels = labels
+		return &fleet.MDMProfileTargetsPreview{HostsCount: 1, Hosts: []fleet.MDMProfileTargetHost{{ID: 1, DisplayName: "host1"}}}, nil
+	}
+
+	t.Run("authorization", func(t *testing.T) {
+		_, err := svc.PreviewMDMProfileTargets(test.UserContext(ctx, test.UserObserver), nil, "darwin", fleet.MDMProfileLabelTargeting{})
+		checkAuthErr(t, true, err)
+
+		_, err = svc.PreviewMDMProfileTargets(test.UserContext(ctx, test.UserMaintainer), nil, "darwin", fleet.MDMProfileLabelTargeting{})
+		checkAuthErr(t, false, err)
+	})
+
+	ctx = test.UserContext(ctx, test.UserAdmin)
+
+	t.Run("invalid platform", func(t *testing.T) {
+		ds.PreviewMDMProfileTargetsFuncInvoked = false
+		_, err := svc.PreviewMDMProfileTargets(ctx, nil, "linux", fleet.MDMProfileLabelTargeting{})
+		require.ErrorContains(t, err, "platform")
+		require.False(t, ds.PreviewMDMProfileTargetsFuncInvoked)
+	})
+
+	t.Run("label in more than one set", func(t *testing.T) {
+		ds.PreviewMDMProfileTargetsFuncInvoked = false
+		_, err := svc.PreviewMDMProfileTargets(ctx, nil, "windows", fleet.MDMProfileLabelTargeting{Labels: []string{"a"}, LabelsExcludeAny: []string{"a"}})
+		require.ErrorContains(t, err, `label "a" cannot be in both labels and labels_exclude_any`)
+		require.False(t, ds.PreviewMDMProfileTargetsFuncInvoked)
+	})
+
+	t.Run("unknown label", func(t *testing.T) {
+		ds.PreviewMDMProfileTargetsFuncInvoked = false
+		_, err := svc.PreviewMDMProfileTargets(ctx, nil, "windows", fleet.MDMProfileLabelTargeting{LabelsIncludeAny: []string{"c"}})
+		require.ErrorContains(t, err, "some or all the labels provided don't exist")
+		require.False(t, ds.PreviewMDMProfileTargetsFuncInvoked)
+	})
+
+	t.Run("labels are passed with their sets", func(t *testing.T) {
+		preview, err := svc.PreviewMDMProfileTargets(ctx, ptr.Uint(1), "darwin", fleet.MDMProfileLabelTargeting{LabelsIncludeAny: []string{"a"}, LabelsExcludeAny: []string{"b"}})
+		require.NoError(t, err)
+		require.True(t, ds.PreviewMDMProfileTargetsFuncInvoked)
+		require.EqualValues(t, 1, preview.HostsCount)
+		require.Len(t, preview.Hosts, 1)
+		require.ElementsMatch(t, []fleet.ConfigurationProfileLabel{
+			{LabelName: "a", LabelID: 1},
+			{LabelName: "b", LabelID: 2, Exclude: true},
+		}, gotLabels)
+	})
+}
+
 func TestBackwardsCompatProfilesParamUnmarshalJSON(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,852 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/publicip"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/go-kit/kit/log/level"
+)
+
+// mfaTokenSize is the size in bytes of the random tokens that identify MFA
+// challenges.
+const mfaTokenSize = 32
+
+// newMFALoginChallenge creates the challenge for the second step of the
+// password login of the user, and returns the error that tells the client
+// how to complete the login.
+func (svc *Service) newMFALoginChallenge(ctx context.Context, user *fleet.User) (*fleet.MFARequiredError, error) {
+	token, err := server.GenerateRandomText(mfaTokenSize)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate mfa token")
+	}
+
+	mfaErr := &fleet.MFARequiredError{Token: token}
+	challenge := &fleet.MFAChallenge{
+		Token:   token,
+		UserID:  user.ID,
+		Purpose: fleet.MFAChallengePurposeLogin,
+	}
+
+	secret, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return nil, ctxerr.Wrap(ctx, err, "get user totp secret")
+	}
+	if secret != nil && secret.ConfirmedAt != nil {
+		mfaErr.Methods = append(mfaErr.Methods, fleet.MFAMethodTOTP)
+	}
+
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list user webauthn credentials")
+	}
+	if len(creds) > 0 {
+		rp, err := svc.webAuthnRelyingParty(ctx)
+		if err != nil {
+			return nil, err
+		}
+		challenge.WebAuthnChallenge, err = mfa.NewChallenge()
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "generate webauthn challenge")
+		}
+		credIDs := make([][]byte, 0, len(creds))
+		for _, c := range creds {
+			credIDs = append(credIDs, c.CredentialID)
+		}
+		mfaErr.Methods = append(mfaErr.Methods, fleet.MFAMethodWebAuthn)
+		mfaErr.WebAuthnOptions = rp.NewRequestOptions(challenge.WebAuthnChallenge, credIDs)
+	}
+	mfaErr.Methods = append(mfaErr.Methods, fleet.MFAMethodRecoveryCode)
+
+	if err := svc.ds.NewMFAChallenge(ctx, challenge); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create mfa login challenge")
+	}
+	return mfaErr, nil
+}
+
+func (svc *Service) webAuthnRelyingParty(ctx context.Context) (*mfa.RelyingParty, error) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	rp, err := mfa.NewRelyingParty(appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message:     "WebAuthn requires a valid Fleet server URL",
+			InternalErr: err,
+		})
+	}
+	return rp, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Login with a second factor
+////////////////////////////////////////////////////////////////////////////////
+
+type loginMFARequest struct {
+	fleet.MFALoginPayload
+}
+
+func loginMFAEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*loginMFARequest)
+
+	user, session, err := svc.LoginMFA(ctx, req.MFALoginPayload)
+	if err != nil {
+		return loginResponse{Err: err}, nil
+	}
+	return newLoginResponse(ctx, svc, user, session), nil
+}
+
+// maxMFALoginAttempts is the number of second factors that can be submitted
+// for a login MFA challenge before it is invalidated.
+const maxMFALoginAttempts = 3
+
+func (svc *Service) LoginMFA(ctx context.Context, payload fleet.MFALoginPayload) (*fleet.User, *fleet.Session, error) {
+	// skipauth: No user context available yet to authorize against.
+	svc.authz.SkipAuthorization(ctx)
+
+	logging.WithLevel(logging.WithExtras(logging.WithNoUser(ctx),
+		"op", "login_mfa",
+		"public_ip", publicip.FromContext(ctx),
+	), level.Info)
+
+	// As for the password step, a failure takes ~1s. The challenge is deleted
+	// so that the login must be restarted from the password step, unless an
+	// invalid second factor was submitted and attempts remain.
+	var (
+		err           error
+		email         string
+		keepChallenge bool
+	)
+	defer func(start time.Time) {
+		if err != nil {
+			if payload.Token != "" && !keepChallenge {
+				if err := svc.ds.DeleteMFAChallenge(ctx, payload.Token); err != nil {
+					logging.WithExtras(logging.WithNoUser(ctx),
+						"msg", "failed to delete mfa challenge",
+					)
+				}
+			}
+			if err := svc.ds.NewActivity(ctx, nil, fleet.ActivityTypeUserFailedLogin{
+				Email:    email,
+				PublicIP: publicip.FromContext(ctx),
+			}); err != nil {
+				logging.WithExtras(logging.WithNoUser(ctx),
+					"msg", "failed to generate failed login activity",
+				)
+			}
+			time.Sleep(time.Until(start.Add(1 * time.Second)))
+		}
+	}(time.Now())
+
+	if err = payload.Validate(); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err)
+	}
+
+	challenge, err := svc.ds.MFAChallenge(ctx, payload.Token)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			err = fleet.NewAuthFailedError("invalid mfa token")
+		}
+		return nil, nil, err
+	}
+	if challenge.Purpose != fleet.MFAChallengePurposeLogin || challenge.Expired(svc.clock.Now()) {
+		err = fleet.NewAuthFailedError("invalid mfa token")
+		return nil, nil, err
+	}
+
+	// the attempt is recorded before the factor is verified, so that
+	// concurrent requests with the same challenge can't exceed the limit
+	ok, err := svc.ds.RecordMFAChallengeAttempt(ctx, challenge.Token, maxMFALoginAttempts)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "record mfa challenge attempt")
+	}
+	if !ok {
+		err = fleet.NewAuthFailedError("too many mfa attempts")
+		return nil, nil, err
+	}
+
+	user, err := svc.ds.UserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+	email = user.Email
+
+	switch {
+	case payload.TOTPCode != "":
+		err = svc.verifyLoginTOTP(ctx, user, payload.TOTPCode)
+	case payload.RecoveryCode != "":
+		err = svc.ds.UseUserMFARecoveryCode(ctx, user.ID, mfa.HashRecoveryCode(payload.RecoveryCode))
+		if fleet.IsNotFound(err) {
+			err = fleet.NewAuthFailedError("invalid recovery code")
+		}
+	default:
+		err = svc.verifyLoginWebAuthn(ctx, user, challenge, payload.WebAuthn)
+	}
+	if err != nil {
+		var authErr *fleet.AuthFailedError
+		keepChallenge = errors.As(err, &authErr) && challenge.Attempts+1 < maxMFALoginAttempts
+		return nil, nil, err
+	}
+
+	if err = svc.ds.DeleteMFAChallenge(ctx, challenge.Token); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "delete mfa challenge")
+	}
+
+	session, err := svc.makeSession(ctx, user.ID)
+	if err != nil {
+		return nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+
+	if err := svc.ds.NewActivity(ctx, user, fleet.ActivityTypeUserLoggedIn{
+		PublicIP: publicip.FromContext(ctx),
+	}); err != nil {
+		return nil, nil, err
+	}
+	return user, session, nil
+}
+
+func (svc *Service) verifyLoginTOTP(ctx context.Context, user *fleet.User, code string) error {
+	secret, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return fleet.NewAuthFailedError("totp not enabled")
+		}
+		return err
+	}
+	if secret.ConfirmedAt == nil {
+		return fleet.NewAuthFailedError("totp not enabled")
+	}
+
+	step, ok := mfa.ValidateTOTP(secret.Secret, code, svc.clock.Now(), secret.LastUsedStep)
+	if !ok {
+		return fleet.NewAuthFailedError("invalid totp code")
+	}
+	// the datastore only accepts a step after the last used one, which protects
+	// against concurrent replays of the same code
+	ok, err = svc.ds.MarkUserTOTPStepUsed(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fleet.NewAuthFailedError("totp code already used")
+	}
+	return nil
+}
+
+func (svc *Service) verifyLoginWebAuthn(ctx context.Context, user *fleet.User, challenge *fleet.MFAChallenge, assertion *fleet.WebAuthnAssertion) error {
+	if len(challenge.WebAuthnChallenge) == 0 {
+		return fleet.NewAuthFailedError("webauthn not enabled")
+	}
+
+	credID, err := mfa.DecodeBase64URL(assertion.CredentialID)
+	if err != nil {
+		return fleet.NewAuthFailedError("invalid webauthn credential id")
+	}
+	clientDataJSON, err := mfa.DecodeBase64URL(assertion.ClientDataJSON)
+	if err != nil {
+		return fleet.NewAuthFailedError("invalid webauthn client data")
+	}
+	authData, err := mfa.DecodeBase64URL(assertion.AuthenticatorData)
+	if err != nil {
+		return fleet.NewAuthFailedError("invalid webauthn authenticator data")
+	}
+	signature, err := mfa.DecodeBase64URL(assertion.Signature)
+	if err != nil {
+		return fleet.NewAuthFailedError("invalid webauthn signature")
+	}
+
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	var cred *fleet.UserWebAuthnCredential
+	for _, c := range creds {
+		if bytes.Equal(c.CredentialID, credID) {
+			cred = c
+			break
+		}
+	}
+	if cred == nil {
+		return fleet.NewAuthFailedError("unknown webauthn credential")
+	}
+
+	rp, err := svc.webAuthnRelyingParty(ctx)
+	if err != nil {
+		return err
+	}
+	signCount, err := rp.VerifyAssertion(challenge.WebAuthnChallenge, &mfa.Credential{
+		ID:        cred.CredentialID,
+		PublicKey: cred.PublicKey,
+		SignCount: cred.SignCount,
+	}, clientDataJSON, authData, signature)
+	if err != nil {
+		return fleet.NewAuthFailedError(err.Error())
+	}
+	return svc.ds.MarkUserWebAuthnCredentialUsed(ctx, cred.ID, signCount)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MFA enrollment enforcement
+////////////////////////////////////////////////////////////////////////////////
+
+func (svc *Service) MFAEnrollmentRequired(ctx context.Context, user *fleet.User) (bool, error) {
+	if user.MFAEnabled || user.SSOEnabled || user.APIOnly {
+		// avoid loading the app config when it cannot make a difference
+		return false, nil
+	}
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	return user.IsMFAEnrollmentRequired(appConfig.MFASettings), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get MFA status
+////////////////////////////////////////////////////////////////////////////////
+
+type getMFAStatusResponse struct {
+	MFA *fleet.UserMFAStatus `json:"mfa,omitempty"`
+	Err error                `json:"error,omitempty"`
+}
+
+func (r getMFAStatusResponse) error() error { return r.Err }
+
+func getMFAStatusEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	status, err := svc.GetMFAStatus(ctx)
+	if err != nil {
+		return getMFAStatusResponse{Err: err}, nil
+	}
+	return getMFAStatusResponse{MFA: status}, nil
+}
+
+// authorizeSelfMFA checks that the viewer is a password-based user allowed to
+// manage their own second factors and returns that user.
+func (svc *Service) authorizeSelfMFA(ctx context.Context) (*fleet.User, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: vc.UserID()}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !vc.IsLoggedIn() {
+		return nil, fleet.NewPermissionError("not logged in")
+	}
+	if vc.User.SSOEnabled {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("mfa", "multi-factor authentication is not available for SSO users"))
+	}
+	if vc.User.APIOnly {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("mfa", "multi-factor authentication is not available for API-only users"))
+	}
+	return vc.User, nil
+}
+
+func (svc *Service) GetMFAStatus(ctx context.Context) (*fleet.UserMFAStatus, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: vc.UserID()}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	user := vc.User
+
+	status := &fleet.UserMFAStatus{
+		WebAuthnCredentials: []*fleet.UserWebAuthnCredential{},
+	}
+	secret, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return nil, ctxerr.Wrap(ctx, err, "get user totp secret")
+	}
+	status.TOTPEnabled = secret != nil && secret.ConfirmedAt != nil
+
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list user webauthn credentials")
+	}
+	if creds != nil {
+		status.WebAuthnCredentials = creds
+	}
+
+	status.RecoveryCodesRemaining, err = svc.ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "count user recovery codes")
+	}
+
+	status.Enabled = status.TOTPEnabled || len(creds) > 0
+	if !status.Enabled {
+		status.EnrollmentRequired, err = svc.MFAEnrollmentRequired(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TOTP enrollment
+////////////////////////////////////////////////////////////////////////////////
+
+type beginTOTPEnrollmentResponse struct {
+	TOTP *fleet.TOTPEnrollment `json:"totp,omitempty"`
+	Err  error                 `json:"error,omitempty"`
+}
+
+func (r beginTOTPEnrollmentResponse) error() error { return r.Err }
+
+func beginTOTPEnrollmentEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	enrollment, err := svc.BeginTOTPEnrollment(ctx)
+	if err != nil {
+		return beginTOTPEnrollmentResponse{Err: err}, nil
+	}
+	return beginTOTPEnrollmentResponse{TOTP: enrollment}, nil
+}
+
+func (svc *Service) BeginTOTPEnrollment(ctx context.Context) (*fleet.TOTPEnrollment, error) {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// TOTP secrets are encrypted at rest with the server private key
+	if svc.config.Server.PrivateKey == "" {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't enable an authenticator app. The server private key (server.private_key) is not configured.",
+		})
+	}
+
+	existing, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return nil, ctxerr.Wrap(ctx, err, "get user totp secret")
+	}
+	if existing != nil && existing.ConfirmedAt != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("totp", "an authenticator app is already enabled, disable it first"))
+	}
+
+	secret, err := mfa.GenerateTOTPSecret()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate totp secret")
+	}
+	if err := svc.ds.SetUserTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save totp secret")
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	issuer := "Fleet"
+	if appConfig.OrgInfo.OrgName != "" {
+		issuer = appConfig.OrgInfo.OrgName + " Fleet"
+	}
+	return &fleet.TOTPEnrollment{
+		Secret: secret,
+		URI:    mfa.TOTPURI(issuer, user.Email, secret),
+	}, nil
+}
+
+type confirmTOTPEnrollmentRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Err           error    `json:"error,omitempty"`
+}
+
+func (r mfaRecoveryCodesResponse) error() error { return r.Err }
+
+func confirmTOTPEnrollmentEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*confirmTOTPEnrollmentRequest)
+	codes, err := svc.ConfirmTOTPEnrollment(ctx, req.Code)
+	if err != nil {
+		return mfaRecoveryCodesResponse{Err: err}, nil
+	}
+	return mfaRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) ConfirmTOTPEnrollment(ctx context.Context, code string) ([]string, error) {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("totp", "no pending authenticator app enrollment"))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get user totp secret")
+	}
+	if secret.ConfirmedAt != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("totp", "an authenticator app is already enabled"))
+	}
+
+	step, ok := mfa.ValidateTOTP(secret.Secret, code, svc.clock.Now(), 0)
+	if !ok {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("code", "invalid code"))
+	}
+
+	firstFactor := !user.MFAEnabled
+	if err := svc.ds.ConfirmUserTOTPSecret(ctx, user.ID, step); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "confirm totp secret")
+	}
+	if err := svc.ds.NewActivity(ctx, user, fleet.ActivityTypeEnabledUserMFA{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+		Method:    fleet.MFAMethodTOTP,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for enabled mfa")
+	}
+
+	if !firstFactor {
+		return nil, nil
+	}
+	return svc.replaceMFARecoveryCodes(ctx, user.ID)
+}
+
+type disableTOTPResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r disableTOTPResponse) error() error { return r.Err }
+
+func disableTOTPEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	if err := svc.DisableTOTP(ctx); err != nil {
+		return disableTOTPResponse{Err: err}, nil
+	}
+	return disableTOTPResponse{}, nil
+}
+
+func (svc *Service) DisableTOTP(ctx context.Context) error {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return err
+	}
+
+	secret, err := svc.ds.UserTOTPSecret(ctx, user.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get user totp secret")
+	}
+	if err := svc.ds.DeleteUserTOTPSecret(ctx, user.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete user totp secret")
+	}
+	if secret.ConfirmedAt == nil {
+		// only a pending enrollment was cancelled
+		return nil
+	}
+	return svc.afterMFAFactorRemoved(ctx, user, fleet.MFAMethodTOTP)
+}
+
+// afterMFAFactorRemoved records the removal of a second factor and deletes
+// the recovery codes if it was the last one.
+func (svc *Service) afterMFAFactorRemoved(ctx context.Context, user *fleet.User, method fleet.MFAMethod) error {
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDisabledUserMFA{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+		Method:    method,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for disabled mfa")
+	}
+
+	updated, err := svc.ds.UserByID(ctx, user.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "reload user")
+	}
+	if !updated.MFAEnabled {
+		if err := svc.ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, nil); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete recovery codes")
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WebAuthn registration
+////////////////////////////////////////////////////////////////////////////////
+
+type beginWebAuthnRegistrationResponse struct {
+	WebAuthn *fleet.WebAuthnRegistrationOptions `json:"webauthn,omitempty"`
+	Err      error                              `json:"error,omitempty"`
+}
+
+func (r beginWebAuthnRegistrationResponse) error() error { return r.Err }
+
+func beginWebAuthnRegistrationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	opts, err := svc.BeginWebAuthnRegistration(ctx)
+	if err != nil {
+		return beginWebAuthnRegistrationResponse{Err: err}, nil
+	}
+	return beginWebAuthnRegistrationResponse{WebAuthn: opts}, nil
+}
+
+func (svc *Service) BeginWebAuthnRegistration(ctx context.Context) (*fleet.WebAuthnRegistrationOptions, error) {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rp, err := svc.webAuthnRelyingParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list user webauthn credentials")
+	}
+	existing := make([][]byte, 0, len(creds))
+	for _, c := range creds {
+		existing = append(existing, c.CredentialID)
+	}
+
+	token, err := server.GenerateRandomText(mfaTokenSize)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate mfa token")
+	}
+	webAuthnChallenge, err := mfa.NewChallenge()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate webauthn challenge")
+	}
+	if err := svc.ds.NewMFAChallenge(ctx, &fleet.MFAChallenge{
+		Token:             token,
+		UserID:            user.ID,
+		Purpose:           fleet.MFAChallengePurposeWebAuthnRegistration,
+		WebAuthnChallenge: webAuthnChallenge,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create webauthn registration challenge")
+	}
+
+	return &fleet.WebAuthnRegistrationOptions{
+		ChallengeToken: token,
+		PublicKey:      rp.NewCreationOptions(webAuthnChallenge, user.ID, user.Email, user.Name, existing),
+	}, nil
+}
+
+type finishWebAuthnRegistrationRequest struct {
+	fleet.WebAuthnRegistrationPayload
+}
+
+type finishWebAuthnRegistrationResponse struct {
+	Credential    *fleet.UserWebAuthnCredential `json:"credential,omitempty"`
+	RecoveryCodes []string                      `json:"recovery_codes,omitempty"`
+	Err           error                         `json:"error,omitempty"`
+}
+
+func (r finishWebAuthnRegistrationResponse) error() error { return r.Err }
+
+func finishWebAuthnRegistrationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*finishWebAuthnRegistrationRequest)
+	cred, codes, err := svc.FinishWebAuthnRegistration(ctx, req.WebAuthnRegistrationPayload)
+	if err != nil {
+		return finishWebAuthnRegistrationResponse{Err: err}, nil
+	}
+	return finishWebAuthnRegistrationResponse{Credential: cred, RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) FinishWebAuthnRegistration(ctx context.Context, payload fleet.WebAuthnRegistrationPayload) (*fleet.UserWebAuthnCredential, []string, error) {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if payload.Name == "" {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("name", "missing credential name"))
+	}
+
+	challenge, err := svc.ds.MFAChallenge(ctx, payload.ChallengeToken)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("challenge_token", "invalid challenge token"))
+		}
+		return nil, nil, ctxerr.Wrap(ctx, err, "get webauthn registration challenge")
+	}
+	// the challenge can only be used once
+	if err := svc.ds.DeleteMFAChallenge(ctx, challenge.Token); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "delete webauthn registration challenge")
+	}
+	if challenge.UserID != user.ID || challenge.Purpose != fleet.MFAChallengePurposeWebAuthnRegistration ||
+		challenge.Expired(svc.clock.Now()) {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("challenge_token", "invalid challenge token"))
+	}
+
+	clientDataJSON, err := mfa.DecodeBase64URL(payload.ClientDataJSON)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("client_data_json", "invalid base64url value"))
+	}
+	attestation, err := mfa.DecodeBase64URL(payload.AttestationObject)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("attestation_object", "invalid base64url value"))
+	}
+
+	rp, err := svc.webAuthnRelyingParty(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	verified, err := rp.VerifyRegistration(challenge.WebAuthnChallenge, clientDataJSON, attestation)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("webauthn", err.Error()))
+	}
+
+	firstFactor := !user.MFAEnabled
+	cred, err := svc.ds.NewUserWebAuthnCredential(ctx, &fleet.UserWebAuthnCredential{
+		UserID:       user.ID,
+		Name:         payload.Name,
+		CredentialID: verified.ID,
+		PublicKey:    verified.PublicKey,
+		SignCount:    verified.SignCount,
+	})
+	if err != nil {
+		var existsErr fleet.AlreadyExistsError
+		if errors.As(err, &existsErr) {
+			return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("webauthn", "this credential is already registered"))
+		}
+		return nil, nil, ctxerr.Wrap(ctx, err, "save webauthn credential")
+	}
+	if err := svc.ds.NewActivity(ctx, user, fleet.ActivityTypeEnabledUserMFA{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+		Method:    fleet.MFAMethodWebAuthn,
+	}); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "create activity for enabled mfa")
+	}
+
+	if !firstFactor {
+		return cred, nil, nil
+	}
+	codes, err := svc.replaceMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cred, codes, nil
+}
+
+type deleteWebAuthnCredentialRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteWebAuthnCredentialResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteWebAuthnCredentialResponse) error() error { return r.Err }
+
+func deleteWebAuthnCredentialEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteWebAuthnCredentialRequest)
+	if err := svc.DeleteWebAuthnCredential(ctx, req.ID); err != nil {
+		return deleteWebAuthnCredentialResponse{Err: err}, nil
+	}
+	return deleteWebAuthnCredentialResponse{}, nil
+}
+
+func (svc *Service) DeleteWebAuthnCredential(ctx context.Context, id uint) error {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.ds.DeleteUserWebAuthnCredential(ctx, user.ID, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete webauthn credential")
+	}
+	return svc.afterMFAFactorRemoved(ctx, user, fleet.MFAMethodWebAuthn)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Recovery codes
+////////////////////////////////////////////////////////////////////////////////
+
+func regenerateMFARecoveryCodesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	codes, err := svc.RegenerateMFARecoveryCodes(ctx)
+	if err != nil {
+		return mfaRecoveryCodesResponse{Err: err}, nil
+	}
+	return mfaRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) RegenerateMFARecoveryCodes(ctx context.Context) ([]string, error) {
+	user, err := svc.authorizeSelfMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("mfa", "multi-factor authentication is not enabled"))
+	}
+	return svc.replaceMFARecoveryCodes(ctx, user.ID)
+}
+
+// replaceMFARecoveryCodes generates and stores new recovery codes for the
+// user, invalidating the previous ones. The codes are returned in clear text,
+// only their hash is stored.
+func (svc *Service) replaceMFARecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate recovery codes")
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, mfa.HashRecoveryCode(c))
+	}
+	if err := svc.ds.ReplaceUserMFARecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save recovery codes")
+	}
+	return codes, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Reset MFA of a user
+////////////////////////////////////////////////////////////////////////////////
+
+type resetUserMFARequest struct {
+	ID uint `url:"id"`
+}
+
+type resetUserMFAResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r resetUserMFAResponse) error() error { return r.Err }
+
+func resetUserMFAEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*resetUserMFARequest)
+	if err := svc.ResetUserMFA(ctx, req.ID); err != nil {
+		return resetUserMFAResponse{Err: err}, nil
+	}
+	return resetUserMFAResponse{}, nil
+}
+
+func (svc *Service) ResetUserMFA(ctx context.Context, userID uint) error {
+	user, err := svc.ds.UserByID(ctx, userID)
+	if err != nil {
+		setAuthCheckedOnPreAuthErr(ctx)
+		return ctxerr.Wrap(ctx, err, "load user")
+	}
+	// same permission as changing the password of the user
+	if err := svc.authz.Authorize(ctx, user, fleet.ActionChangePassword); err != nil {
+		return err
+	}
+
+	if err := svc.ds.DeleteUserMFA(ctx, user.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete user mfa")
+	}
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDisabledUserMFA{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for disabled mfa")
+	}
+	return nil
+}
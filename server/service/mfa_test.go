@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+// mockMFAStore sets up the MFA methods of the mock datastore for a single
+// user, with the state kept in memory.
+type mockMFAStore struct {
+	user          *fleet.User
+	totp          *fleet.UserTOTPSecret
+	challenges    map[string]*fleet.MFAChallenge
+	recoveryCodes map[string]bool
+}
+
+func newMockMFAStore(ds *mock.Store, user *fleet.User) *mockMFAStore {
+	m := &mockMFAStore{
+		user:          user,
+		challenges:    make(map[string]*fleet.MFAChallenge),
+		recoveryCodes: make(map[string]bool),
+	}
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"}}, nil
+	}
+	ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+		return m.user, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return m.user, nil
+	}
+	ds.NewSessionFunc = func(ctx context.Context, userID uint, sessionKey, ipAddress, userAgent string) (*fleet.Session, error) {
+		return &fleet.Session{ID: 1, UserID: userID, Key: sessionKey}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.SetUserTOTPSecretFunc = func(ctx context.Context, userID uint, secret string) error {
+		m.totp = &fleet.UserTOTPSecret{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+		return nil
+	}
+	ds.UserTOTPSecretFunc = func(ctx context.Context, userID uint) (*fleet.UserTOTPSecret, error) {
+		if m.totp == nil {
+			return nil, &notFoundError{}
+		}
+		return m.totp, nil
+	}
+	ds.ConfirmUserTOTPSecretFunc = func(ctx context.Context, userID uint, step int64) error {
+		m.totp.ConfirmedAt = ptr.Time(time.Now())
+		m.totp.LastUsedStep = step
+		m.user.MFAEnabled = true
+		return nil
+	}
+	ds.MarkUserTOTPStepUsedFunc = func(ctx context.Context, userID uint, step int64) (bool, error) {
+		if step <= m.totp.LastUsedStep {
+			return false, nil
+		}
+		m.totp.LastUsedStep = step
+		return true, nil
+	}
+	ds.ListUserWebAuthnCredentialsFunc = func(ctx context.Context, userID uint) ([]*fleet.UserWebAuthnCredential, error) {
+		return nil, nil
+	}
+	ds.ReplaceUserMFARecoveryCodesFunc = func(ctx context.Context, userID uint, codeHashes []string) error {
+		m.recoveryCodes = make(map[string]bool)
+		for _, h := range codeHashes {
+			m.recoveryCodes[h] = true
+		}
+		return nil
+	}
+	ds.UseUserMFARecoveryCodeFunc = func(ctx context.Context, userID uint, codeHash string) error {
+		if !m.recoveryCodes[codeHash] {
+			return &notFoundError{}
+		}
+		delete(m.recoveryCodes, codeHash)
+		return nil
+	}
+	ds.CountUserMFARecoveryCodesFunc = func(ctx context.Context, userID uint) (int, error) {
+		return len(m.recoveryCodes), nil
+	}
+	ds.NewMFAChallengeFunc = func(ctx context.Context, challenge *fleet.MFAChallenge) error {
+		c := *challenge
+		c.CreatedAt = time.Now()
+		m.challenges[c.Token] = &c
+		return nil
+	}
+	ds.MFAChallengeFunc = func(ctx context.Context, token string) (*fleet.MFAChallenge, error) {
+		c, ok := m.challenges[token]
+		if !ok {
+			return nil, &notFoundError{}
+		}
+		cc := *c
+		return &cc, nil
+	}
+	ds.RecordMFAChallengeAttemptFunc = func(ctx context.Context, token string, maxAttempts int) (bool, error) {
+		c, ok := m.challenges[token]
+		if !ok || c.Attempts >= maxAttempts {
+			return false, nil
+		}
+		c.Attempts++
+		return true, nil
+	}
+	ds.DeleteMFAChallengeFunc = func(ctx context.Context, token string) error {
+		delete(m.challenges, token)
+		return nil
+	}
+	return m
+}
+
+func TestMFATOTPEnrollmentAndLogin(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	user := &fleet.User{ID: 42, Name: "Foo", Email: "foo@example.com", GlobalRole: ptr.String(fleet.RoleObserver)}
+	require.NoError(t, user.SetPassword(test.GoodPassword, 10, 10))
+	m := newMockMFAStore(ds, user)
+	userCtx := viewer.NewContext(ctx, viewer.Viewer{User: user, Session: &fleet.Session{ID: 1, UserID: user.ID}})
+
+	status, err := svc.GetMFAStatus(userCtx)
+	require.NoError(t, err)
+	require.False(t, status.Enabled)
+	require.False(t, status.TOTPEnabled)
+	require.Empty(t, status.WebAuthnCredentials)
+
+	// no second factor yet, the login completes with the password
+	_, session, err := svc.Login(ctx, user.Email, test.GoodPassword)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+
+	enrollment, err := svc.BeginTOTPEnrollment(userCtx)
+	require.NoError(t, err)
+	require.NotEmpty(t, enrollment.Secret)
+	require.Contains(t, enrollment.URI, "otpauth://totp/")
+	require.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	// an invalid code does not confirm the secret
+	_, err = svc.ConfirmTOTPEnrollment(userCtx, "000000x")
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	require.False(t, ds.ConfirmUserTOTPSecretFuncInvoked)
+
+	code, err := mfa.TOTPCode(enrollment.Secret, mfa.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	codes, err := svc.ConfirmTOTPEnrollment(userCtx, code)
+	require.NoError(t, err)
+	require.Len(t, codes, mfa.RecoveryCodeCount)
+	require.True(t, user.MFAEnabled)
+
+	// cannot enroll a second authenticator app
+	_, err = svc.BeginTOTPEnrollment(userCtx)
+	require.ErrorAs(t, err, &iae)
+
+	status, err = svc.GetMFAStatus(userCtx)
+	require.NoError(t, err)
+	require.True(t, status.Enabled)
+	require.True(t, status.TOTPEnabled)
+	require.Equal(t, mfa.RecoveryCodeCount, status.RecoveryCodesRemaining)
+
+	// the password login now requires a second factor
+	_, _, err = svc.Login(ctx, user.Email, test.GoodPassword)
+	var mfaErr *fleet.MFARequiredError
+	require.ErrorAs(t, err, &mfaErr)
+	require.NotEmpty(t, mfaErr.Token)
+	require.Equal(t, []fleet.MFAMethod{fleet.MFAMethodTOTP, fleet.MFAMethodRecoveryCode}, mfaErr.Methods)
+	require.Nil(t, mfaErr.WebAuthnOptions)
+
+	// the code used to confirm the enrollment cannot be reused
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, TOTPCode: code})
+	var authFailed *fleet.AuthFailedError
+	require.ErrorAs(t, err, &authFailed)
+	require.Len(t, m.challenges, 1)
+
+	// the challenge is invalidated after too many failed attempts
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, TOTPCode: "000000"})
+	require.ErrorAs(t, err, &authFailed)
+	require.Len(t, m.challenges, 1)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, TOTPCode: "000000"})
+	require.ErrorAs(t, err, &authFailed)
+	require.Empty(t, m.challenges)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, RecoveryCode: codes[0]})
+	require.ErrorAs(t, err, &authFailed)
+	require.Len(t, m.recoveryCodes, mfa.RecoveryCodeCount)
+
+	// a challenge that already reached the limit cannot be used, e.g. by
+	// concurrent attempts
+	_, _, err = svc.Login(ctx, user.Email, test.GoodPassword)
+	require.ErrorAs(t, err, &mfaErr)
+	m.challenges[mfaErr.Token].Attempts = maxMFALoginAttempts
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, RecoveryCode: codes[0]})
+	require.ErrorAs(t, err, &authFailed)
+	require.Empty(t, m.challenges)
+	require.Len(t, m.recoveryCodes, mfa.RecoveryCodeCount)
+
+	// log in with a recovery code, which can only be used once
+	_, _, err = svc.Login(ctx, user.Email, test.GoodPassword)
+	require.ErrorAs(t, err, &mfaErr)
+	loggedIn, session, err := svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, RecoveryCode: codes[0]})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, loggedIn.ID)
+	require.NotEmpty(t, session.Key)
+	require.Empty(t, m.challenges)
+
+	_, _, err = svc.Login(ctx, user.Email, test.GoodPassword)
+	require.ErrorAs(t, err, &mfaErr)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, RecoveryCode: codes[0]})
+	require.ErrorAs(t, err, &authFailed)
+
+	// the mfa token is required, and a single factor must be provided
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{TOTPCode: code})
+	require.ErrorAs(t, err, &iae)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: "abc", TOTPCode: code, RecoveryCode: codes[1]})
+	require.ErrorAs(t, err, &iae)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: "no-such-token", RecoveryCode: codes[1]})
+	require.ErrorAs(t, err, &authFailed)
+
+	// an expired challenge cannot be used
+	_, _, err = svc.Login(ctx, user.Email, test.GoodPassword)
+	require.ErrorAs(t, err, &mfaErr)
+	m.challenges[mfaErr.Token].CreatedAt = time.Now().Add(-fleet.MFAChallengeExpiry)
+	_, _, err = svc.LoginMFA(ctx, fleet.MFALoginPayload{Token: mfaErr.Token, RecoveryCode: codes[1]})
+	require.ErrorAs(t, err, &authFailed)
+
+	// the server private key is required to enroll an authenticator app
+	cfg := config.TestConfig()
+	cfg.Server.PrivateKey = ""
+	svc, _ = newTestServiceWithConfig(t, ds, cfg, nil, nil)
+	_, err = svc.BeginTOTPEnrollment(userCtx)
+	require.ErrorContains(t, err, "private key")
+}
+
+func TestMFAEnrollmentRequired(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	var required bool
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MFASettings: fleet.MFASettings{Required: required}}, nil
+	}
+
+	cases := []struct {
+		desc     string
+		user     *fleet.User
+		required bool
+		want     bool
+	}{
+		{"not required", &fleet.User{ID: 1}, false, false},
+		{"required", &fleet.User{ID: 1}, true, true},
+		{"required, enrolled", &fleet.User{ID: 1, MFAEnabled: true}, true, false},
+		{"required, sso user", &fleet.User{ID: 1, SSOEnabled: true}, true, false},
+		{"required, api-only user", &fleet.User{ID: 1, APIOnly: true}, true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			required = c.required
+			got, err := svc.MFAEnrollmentRequired(ctx, c.user)
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+		})
+	}
+
+	// the enrollment endpoints are allowed even if the enrollment is required
+	required = true
+	user := &fleet.User{ID: 1}
+	for _, c := range []struct {
+		method, path string
+		allowed      bool
+	}{
+		{http.MethodGet, "/api/latest/fleet/me", true},
+		{http.MethodGet, "/api/v1/fleet/me/mfa", true},
+		{http.MethodPost, "/api/v1/fleet/me/mfa/totp/confirm", true},
+		{http.MethodPost, "/api/v1/fleet/logout", true},
+		{http.MethodGet, "/api/v1/fleet/config", true},
+		{http.MethodPatch, "/api/v1/fleet/config", false},
+		{http.MethodGet, "/api/v1/fleet/hosts", false},
+		{http.MethodGet, "/api/v1/fleet/mfa", false},
+	} {
+		reqCtx := context.WithValue(ctx, kithttp.ContextKeyRequestMethod, c.method)
+		reqCtx = context.WithValue(reqCtx, kithttp.ContextKeyRequestPath, c.path)
+		err := checkMFAEnrollment(reqCtx, svc, user)
+		if c.allowed {
+			require.NoError(t, err, c.path)
+		} else {
+			require.ErrorIs(t, err, fleet.ErrMFAEnrollmentRequired, c.path)
+		}
+	}
+}
+
+func TestMFAAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	target := &fleet.User{ID: 999, Name: "target", Email: "target@example.com"}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return target, nil
+	}
+	ds.DeleteUserMFAFunc = func(ctx context.Context, userID uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global maintainer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleMaintainer)}, true},
+		{"global observer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team admin", &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+		{"self", &fleet.User{ID: 999}, false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			err := svc.ResetUserMFA(ctx, target.ID)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	// SSO and API-only users cannot manage second factors
+	for _, u := range []*fleet.User{{ID: 1, SSOEnabled: true}, {ID: 1, APIOnly: true}} {
+		ctx := viewer.NewContext(ctx, viewer.Viewer{User: u, Session: &fleet.Session{ID: 1}})
+		_, err := svc.BeginTOTPEnrollment(ctx)
+		var iae *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &iae)
+	}
+}
@@ -1189,8 +1189,46 @@ func (svc *Service) GetMDMWindowsTOSContent(ctx context.Context, redirectUri str
 		return "", ctxerr.Wrap(ctx, err, "issue generating TOS content")
 	}
 
+	// the host is not known yet at this point of the enrollment, the global
+	// locale and branding are used.
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get app config")
+	}
+	var messages fleet.LocalizedMessages
+	if locale := fleet.ResolveLocale(appConfig, nil); locale != fleet.DefaultLocale {
+		translations, err := svc.ds.ListTranslations(ctx)
+		if err != nil {
+			return "", ctxerr.Wrap(ctx, err, "list translations")
+		}
+		messages = fleet.NewLocalizedMessages(locale, translations)
+	}
+
+	// the page is rendered on a light background by default, so the logo for
+	// light backgrounds is preferred.
+	branding := fleet.ResolveBranding(appConfig, nil)
+	logoURL := branding.OrgLogoURLLightBackground
+	if logoURL == "" {
+		logoURL = branding.OrgLogoURL
+	}
+	primaryColor, backgroundColor := branding.PrimaryColor, branding.BackgroundColor
+	if primaryColor == "" {
+		primaryColor = "#192147"
+	}
+	if backgroundColor == "" {
+		backgroundColor = "#fff"
+	}
+
 	var htmlBuf bytes.Buffer
-	err = tmpl.Execute(&htmlBuf, map[string]string{"RedirectURL": redirectUri, "ClientData": reqID})
+	err = tmpl.Execute(&htmlBuf, map[string]any{
+		"RedirectURL":     redirectUri,
+		"ClientData":      reqID,
+		"Messages":        messages,
+		"OrgName":         branding.OrgName,
+		"OrgLogoURL":      logoURL,
+		"PrimaryColor":    primaryColor,
+		"BackgroundColor": backgroundColor,
+	})
 	if err != nil {
 		return "", ctxerr.Wrap(ctx, err, "executing TOS template content")
 	}
@@ -2205,6 +2243,10 @@ func ReconcileWindowsProfiles(ctx context.Context, ds fleet.Datastore, logger ki
 		return ctxerr.Wrap(ctx, err, "get profile contents")
 	}
 
+	// failedProfiles maps the UUIDs of the profiles that could not be turned
+	// into a command (e.g. because a secret variable doesn't exist) to the
+	// reason, which is reported as the detail of the failed host profiles.
+	failedProfiles := make(map[string]string)
 	for profUUID, target := range installTargets {
 		p, ok := profileContents[profUUID]
 		if !ok {
@@ -2212,15 +2254,31 @@ func ReconcileWindowsProfiles(ctx context.Context, ds fleet.Datastore, logger ki
 			return ctxerr.Wrapf(ctx, err, "missing profile content for profile %s", profUUID)
 		}
 
-		command, err := buildCommandFromProfileBytes(p, target.cmdUUID)
+		expanded, err := expandSecretVariables(ctx, ds, string(p), true)
 		if err != nil {
 			level.Info(logger).Log("err", err, "profile_uuid", profUUID)
+			failedProfiles[profUUID] = err.Error()
+			continue
+		}
+
+		command, err := buildCommandFromProfileBytes([]byte(expanded), target.cmdUUID)
+		if err != nil {
+			level.Info(logger).Log("err", err, "profile_uuid", profUUID)
+			failedProfiles[profUUID] = err.Error()
 			continue
 		}
 		if err := ds.MDMWindowsInsertCommandForHosts(ctx, target.hostUUIDs, command); err != nil {
 			return ctxerr.Wrap(ctx, err, "inserting commands for hosts")
 		}
 	}
+	for _, hp := range hostProfiles {
+		if detail, ok := failedProfiles[hp.ProfileUUID]; ok {
+			// no command was enqueued for this profile
+			hp.CommandUUID = ""
+			hp.Status = &fleet.MDMDeliveryFailed
+			hp.Detail = detail
+		}
+	}
 
 	// Windows profiles are just deleted from the DB, the notion of sending
 	// a command to remove a profile doesn't exist.
@@ -38,7 +38,8 @@ func (m *Middleware) AuthzCheck() endpoint.Middleware {
 			if errors.As(err, &authFailedError) ||
 				errors.As(err, &authRequiredError) ||
 				errors.As(err, &authHeaderRequiredError) ||
-				errors.Is(err, fleet.ErrPasswordResetRequired) {
+				errors.Is(err, fleet.ErrPasswordResetRequired) ||
+				errors.Is(err, fleet.ErrMFAEnrollmentRequired) {
 				return nil, err
 			}
 
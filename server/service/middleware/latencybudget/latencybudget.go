@@ -0,0 +1,353 @@
+// Package latencybudget provides an HTTP middleware that reports the requests
+// that take longer than the latency budget configured for their API path,
+// along with the slowest database queries that they executed.
+package latencybudget
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxSlowQueries is the number of slowest queries kept for each request.
+	maxSlowQueries = 5
+	// maxQueryLength is the maximum length of the queries that are logged and
+	// reported.
+	maxQueryLength = 1000
+	// explainTimeout is the timeout to explain the slow queries of a request.
+	explainTimeout = 10 * time.Second
+)
+
+// Explainer explains the execution plan of a query.
+type Explainer interface {
+	// ExplainQuery returns the execution plan of the query, in the JSON format
+	// of MySQL's EXPLAIN statement.
+	ExplainQuery(ctx context.Context, query string, args ...interface{}) (json.RawMessage, error)
+}
+
+type key int
+
+const trackerKey key = 0
+
+// tracker tracks the duration of a request and of the queries that it
+// executes.
+type tracker struct {
+	path   string
+	budget time.Duration
+	start  time.Time
+	keep   bool // keep the arguments of the queries, to explain them
+
+	mu          sync.Mutex
+	queryCount  int
+	queryTime   time.Duration
+	slowQueries []*slowQuery
+}
+
+type slowQuery struct {
+	query    string
+	args     []interface{}
+	duration time.Duration
+	explain  json.RawMessage
+}
+
+func (t *tracker) recordQuery(query string, args []interface{}, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.queryCount++
+	t.queryTime += d
+
+	if len(t.slowQueries) == maxSlowQueries {
+		fastest := t.slowQueries[len(t.slowQueries)-1]
+		if d <= fastest.duration {
+			return
+		}
+		t.slowQueries = t.slowQueries[:len(t.slowQueries)-1]
+	}
+	q := &slowQuery{query: query, duration: d}
+	if t.keep {
+		q.args = args
+	}
+	t.slowQueries = append(t.slowQueries, q)
+	sort.SliceStable(t.slowQueries, func(i, j int) bool {
+		return t.slowQueries[i].duration > t.slowQueries[j].duration
+	})
+}
+
+// Exceeded returns the latency budget of the request of ctx and true if the
+// request has exceeded it.
+func Exceeded(ctx context.Context) (time.Duration, bool) {
+	t, ok := ctx.Value(trackerKey).(*tracker)
+	if !ok {
+		return 0, false
+	}
+	return t.budget, time.Since(t.start) > t.budget
+}
+
+// Middleware is an HTTP middleware that tracks the requests exceeding the
+// latency budget of their API path. It must be used with a gorilla/mux router,
+// the API path is the template of the matched route.
+type Middleware struct {
+	logger    kitlog.Logger
+	budgetFor func(path string) time.Duration
+	explainer Explainer
+
+	budgets sync.Map // API path to its latency budget
+
+	mu        sync.Mutex
+	endpoints map[endpointKey]*EndpointReport
+}
+
+type endpointKey struct {
+	method string
+	path   string
+}
+
+// NewMiddleware creates a middleware using budgetFor to get the latency
+// budget of an API path, a request is not tracked if its budget is 0. If
+// explainer is not nil, the execution plan of the slowest queries of the
+// requests exceeding their budget is logged and reported.
+func NewMiddleware(logger kitlog.Logger, budgetFor func(path string) time.Duration, explainer Explainer) *Middleware {
+	return &Middleware{
+		logger:    logger,
+		budgetFor: budgetFor,
+		explainer: explainer,
+		endpoints: make(map[endpointKey]*EndpointReport),
+	}
+}
+
+// Handler tracks the duration of the requests served by next.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := routePath(r)
+		if path == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		budget := m.budget(path)
+		if budget <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		t := &tracker{path: path, budget: budget, start: time.Now(), keep: m.explainer != nil}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), trackerKey, t)))
+		m.done(r, t, time.Since(t.start))
+	})
+}
+
+func (m *Middleware) budget(path string) time.Duration {
+	if v, ok := m.budgets.Load(path); ok {
+		return v.(time.Duration)
+	}
+	budget := m.budgetFor(path)
+	m.budgets.Store(path, budget)
+	return budget
+}
+
+func (m *Middleware) done(r *http.Request, t *tracker, took time.Duration) {
+	exceeded := took > t.budget
+	m.record(r.Method, t, took, exceeded)
+	if !exceeded {
+		return
+	}
+
+	// the goroutines started by the request may still run queries, the
+	// queries recorded so far are the ones reported.
+	t.mu.Lock()
+	queryCount, queryTime := t.queryCount, t.queryTime
+	queries := append([]*slowQuery(nil), t.slowQueries...)
+	t.mu.Unlock()
+
+	logger := kitlog.With(m.logger, "method", r.Method, "path", t.path, "uri", r.RequestURI)
+	level.Info(logger).Log(
+		"msg", "request exceeded latency budget",
+		"took", took,
+		"budget", t.budget,
+		"db_queries", queryCount,
+		"db_time", queryTime,
+	)
+	if len(queries) == 0 {
+		return
+	}
+
+	if m.explainer == nil {
+		logSlowQueries(logger, queries)
+		return
+	}
+
+	// the response is not complete until the handler returns, so the queries
+	// are explained in the background.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+		defer cancel()
+
+		for _, q := range queries {
+			if !isSelect(q.query) {
+				continue
+			}
+			explain, err := m.explainer.ExplainQuery(ctx, q.query, q.args...)
+			if err != nil {
+				level.Debug(logger).Log("msg", "explain slow query", "err", err)
+				continue
+			}
+			q.explain = explain
+		}
+		logSlowQueries(logger, queries)
+		m.setLastSlowQueries(r.Method, t.path, queries)
+	}()
+}
+
+func logSlowQueries(logger kitlog.Logger, queries []*slowQuery) {
+	for _, q := range queries {
+		keyvals := []interface{}{"msg", "slow query", "query", formatQuery(q.query), "duration", q.duration}
+		if q.explain != nil {
+			keyvals = append(keyvals, "explain", string(q.explain))
+		}
+		level.Info(logger).Log(keyvals...)
+	}
+}
+
+// EndpointReport is the report of an API endpoint whose requests exceeded
+// its latency budget.
+type EndpointReport struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Budget string `json:"budget"`
+	// Requests is the number of requests served, and SlowRequests the number
+	// of requests that exceeded the latency budget.
+	Requests     int       `json:"requests"`
+	SlowRequests int       `json:"slow_requests"`
+	MaxDuration  string    `json:"max_duration"`
+	LastSlowAt   time.Time `json:"last_slow_at"`
+	// LastSlowQueries are the slowest queries of the last request that
+	// exceeded the latency budget.
+	LastSlowQueries []SlowQueryReport `json:"last_slow_queries"`
+
+	maxDuration time.Duration
+}
+
+// SlowQueryReport is a slow query of a request that exceeded its latency
+// budget.
+type SlowQueryReport struct {
+	Query    string          `json:"query"`
+	Duration string          `json:"duration"`
+	Explain  json.RawMessage `json:"explain,omitempty"`
+}
+
+func (m *Middleware) record(method string, t *tracker, took time.Duration, exceeded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := endpointKey{method: method, path: t.path}
+	rep := m.endpoints[k]
+	if rep == nil {
+		rep = &EndpointReport{Method: method, Path: t.path}
+		m.endpoints[k] = rep
+	}
+	rep.Budget = t.budget.String()
+	rep.Requests++
+	if !exceeded {
+		return
+	}
+
+	rep.SlowRequests++
+	rep.LastSlowAt = time.Now().UTC()
+	if took > rep.maxDuration {
+		rep.maxDuration = took
+		rep.MaxDuration = took.String()
+	}
+	t.mu.Lock()
+	rep.LastSlowQueries = slowQueryReports(t.slowQueries)
+	t.mu.Unlock()
+}
+
+func (m *Middleware) setLastSlowQueries(method, path string, queries []*slowQuery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rep := m.endpoints[endpointKey{method: method, path: path}]; rep != nil {
+		rep.LastSlowQueries = slowQueryReports(queries)
+	}
+}
+
+func slowQueryReports(queries []*slowQuery) []SlowQueryReport {
+	reports := make([]SlowQueryReport, 0, len(queries))
+	for _, q := range queries {
+		reports = append(reports, SlowQueryReport{
+			Query:    formatQuery(q.query),
+			Duration: q.duration.String(),
+			Explain:  q.explain,
+		})
+	}
+	return reports
+}
+
+// Report returns the report of the endpoints whose requests exceeded their
+// latency budget, the endpoints with the most slow requests first.
+func (m *Middleware) Report() []EndpointReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reports := make([]EndpointReport, 0, len(m.endpoints))
+	for _, rep := range m.endpoints {
+		if rep.SlowRequests > 0 {
+			reports = append(reports, *rep)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].SlowRequests != reports[j].SlowRequests {
+			return reports[i].SlowRequests > reports[j].SlowRequests
+		}
+		if reports[i].Path != reports[j].Path {
+			return reports[i].Path < reports[j].Path
+		}
+		return reports[i].Method < reports[j].Method
+	})
+	return reports
+}
+
+var (
+	versionRegexp = regexp.MustCompile(`\{fleetversion:[^/]*\}`)
+	varRegexp     = regexp.MustCompile(`\{(\w+):[^/]*\}`)
+	spaceRegexp   = regexp.MustCompile(`\s+`)
+)
+
+// routePath returns the API path of the route matched for the request, with
+// the "_version_" placeholder for the API version and without the patterns
+// of the path variables, e.g. "/api/_version_/fleet/hosts/{id}".
+func routePath(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	tpl = versionRegexp.ReplaceAllString(tpl, "_version_")
+	return varRegexp.ReplaceAllString(tpl, "{$1}")
+}
+
+func formatQuery(query string) string {
+	query = strings.TrimSpace(spaceRegexp.ReplaceAllString(query, " "))
+	if len(query) > maxQueryLength {
+		query = query[:maxQueryLength] + "..."
+	}
+	return query
+}
+
+func isSelect(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
@@ -0,0 +1,219 @@
+package latencybudget
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type queryerFunc func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
+
+func (f queryerFunc) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return f(ctx, query, args)
+}
+
+type explainerFunc func(ctx context.Context, query string, args ...interface{}) (json.RawMessage, error)
+
+func (f explainerFunc) ExplainQuery(ctx context.Context, query string, args ...interface{}) (json.RawMessage, error) {
+	return f(ctx, query, args...)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, the slow queries are
+// logged in the background when they are explained.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestRouter(m *Middleware) *mux.Router {
+	interceptor := NewSQLInterceptor(nil)
+	query := func(ctx context.Context, query string, d time.Duration, args ...driver.NamedValue) {
+		_, err := interceptor.ConnQueryContext(ctx, queryerFunc(func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+			time.Sleep(d)
+			return nil, nil
+		}), query, args)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	r := mux.NewRouter()
+	r.Use(m.Handler)
+	r.HandleFunc("/api/{fleetversion:(?:v1|latest)}/fleet/hosts/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query(ctx, "SELECT * FROM hosts\n\tWHERE id = ?", 30*time.Millisecond, driver.NamedValue{Ordinal: 1, Value: int64(1)})
+		query(ctx, "SELECT 1", time.Millisecond)
+		query(ctx, "UPDATE hosts SET refetch_requested = 1", 20*time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}).Methods(http.MethodGet)
+	r.HandleFunc("/api/{fleetversion:(?:v1|latest)}/fleet/labels", func(w http.ResponseWriter, r *http.Request) {
+		if _, exceeded := Exceeded(r.Context()); exceeded {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}).Methods(http.MethodGet)
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs syncBuffer
+	budgets := map[string]time.Duration{
+		"/api/_version_/fleet/hosts/{id}": 10 * time.Millisecond,
+		"/api/_version_/fleet/labels":     time.Minute,
+	}
+	m := NewMiddleware(kitlog.NewLogfmtLogger(&logs), func(path string) time.Duration { return budgets[path] }, nil)
+	r := newTestRouter(m)
+
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	// the labels request does not exceed its budget
+	require.Equal(t, http.StatusOK, serve("/api/latest/fleet/labels"))
+	require.Empty(t, m.Report())
+	require.Empty(t, logs.String())
+
+	require.Equal(t, http.StatusOK, serve("/api/v1/fleet/hosts/1"))
+	require.Equal(t, http.StatusOK, serve("/api/latest/fleet/hosts/2"))
+
+	report := m.Report()
+	require.Len(t, report, 1)
+	rep := report[0]
+	require.Equal(t, http.MethodGet, rep.Method)
+	require.Equal(t, "/api/_version_/fleet/hosts/{id}", rep.Path)
+	require.Equal(t, "10ms", rep.Budget)
+	require.Equal(t, 2, rep.Requests)
+	require.Equal(t, 2, rep.SlowRequests)
+	require.NotEmpty(t, rep.MaxDuration)
+	require.WithinDuration(t, time.Now(), rep.LastSlowAt, time.Minute)
+
+	// the slowest queries first, with the whitespace collapsed
+	require.Len(t, rep.LastSlowQueries, 3)
+	require.Equal(t, "SELECT * FROM hosts WHERE id = ?", rep.LastSlowQueries[0].Query)
+	require.Equal(t, "UPDATE hosts SET refetch_requested = 1", rep.LastSlowQueries[1].Query)
+	require.Equal(t, "SELECT 1", rep.LastSlowQueries[2].Query)
+	require.Nil(t, rep.LastSlowQueries[0].Explain)
+
+	out := logs.String()
+	require.Contains(t, out, `msg="request exceeded latency budget"`)
+	require.Contains(t, out, `path=/api/_version_/fleet/hosts/{id}`)
+	require.Contains(t, out, `db_queries=3`)
+	require.Contains(t, out, `msg="slow query"`)
+	require.NotContains(t, out, "explain=")
+
+	// a request of an API path without budget is not tracked
+	require.Equal(t, http.StatusNotFound, serve("/api/latest/fleet/packs"))
+	require.Len(t, m.Report(), 1)
+}
+
+func TestMiddlewareExplain(t *testing.T) {
+	var logs syncBuffer
+	var explained []string
+	var mu sync.Mutex
+	explainer := explainerFunc(func(ctx context.Context, query string, args ...interface{}) (json.RawMessage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		explained = append(explained, query)
+		if query == "SELECT 1" {
+			return nil, errors.New("explain failed")
+		}
+		require.Equal(t, []interface{}{int64(1)}, args)
+		return json.RawMessage(`{"query_block":{"select_id":1}}`), nil
+	})
+	m := NewMiddleware(kitlog.NewLogfmtLogger(&logs), func(path string) time.Duration { return time.Millisecond }, explainer)
+	r := newTestRouter(m)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/latest/fleet/hosts/1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// the queries are explained in the background
+	require.Eventually(t, func() bool {
+		report := m.Report()
+		return len(report) == 1 && report[0].LastSlowQueries[0].Explain != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rep := m.Report()[0]
+	require.JSONEq(t, `{"query_block":{"select_id":1}}`, string(rep.LastSlowQueries[0].Explain))
+	require.Nil(t, rep.LastSlowQueries[1].Explain)
+	require.Nil(t, rep.LastSlowQueries[2].Explain)
+
+	// only the SELECT queries are explained
+	mu.Lock()
+	require.ElementsMatch(t, []string{"SELECT * FROM hosts\n\tWHERE id = ?", "SELECT 1"}, explained)
+	mu.Unlock()
+	require.Eventually(t, func() bool {
+		return bytes.Contains([]byte(logs.String()), []byte(`explain="{\"query_block\":{\"select_id\":1}}"`))
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTrackerRecordQuery(t *testing.T) {
+	tr := &tracker{}
+	for i := 1; i <= 10; i++ {
+		tr.recordQuery("q", nil, time.Duration(i%7)*time.Millisecond)
+	}
+	require.Equal(t, 10, tr.queryCount)
+	require.Equal(t, 27*time.Millisecond, tr.queryTime)
+	require.Len(t, tr.slowQueries, maxSlowQueries)
+
+	var durations []time.Duration
+	for _, q := range tr.slowQueries {
+		durations = append(durations, q.duration)
+		require.Nil(t, q.args)
+	}
+	require.Equal(t, []time.Duration{
+		6 * time.Millisecond, 5 * time.Millisecond, 4 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond,
+	}, durations)
+}
+
+func TestExceeded(t *testing.T) {
+	_, exceeded := Exceeded(context.Background())
+	require.False(t, exceeded)
+
+	ctx := context.WithValue(context.Background(), trackerKey, &tracker{budget: time.Hour, start: time.Now()})
+	budget, exceeded := Exceeded(ctx)
+	require.False(t, exceeded)
+	require.Equal(t, time.Hour, budget)
+
+	ctx = context.WithValue(context.Background(), trackerKey, &tracker{budget: time.Second, start: time.Now().Add(-time.Minute)})
+	_, exceeded = Exceeded(ctx)
+	require.True(t, exceeded)
+}
+
+func TestFormatQuery(t *testing.T) {
+	require.Equal(t, "SELECT id FROM hosts WHERE id = ?", formatQuery("\n  SELECT id\n\tFROM hosts\n  WHERE id = ?  "))
+
+	long := formatQuery("SELECT " + string(bytes.Repeat([]byte("a"), 2*maxQueryLength)))
+	require.Len(t, long, maxQueryLength+3)
+
+	require.True(t, isSelect("  select 1"))
+	require.True(t, isSelect("(SELECT 1) UNION (SELECT 2)"))
+	require.False(t, isSelect("UPDATE hosts SET id = 1"))
+	require.False(t, isSelect("SEL"))
+}
@@ -0,0 +1,70 @@
+package latencybudget
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+)
+
+// sqlInterceptor records the duration of the queries executed with the
+// context of a request tracked by the middleware.
+type sqlInterceptor struct {
+	sqlmw.Interceptor
+}
+
+// NewSQLInterceptor returns a SQL interceptor that records the queries of the
+// requests tracked by the middleware, so that the slowest ones are reported if
+// a request exceeds its latency budget. The calls are forwarded to next, if
+// not nil.
+func NewSQLInterceptor(next sqlmw.Interceptor) sqlmw.Interceptor {
+	if next == nil {
+		next = sqlmw.NullInterceptor{}
+	}
+	return &sqlInterceptor{Interceptor: next}
+}
+
+func (in *sqlInterceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := in.Interceptor.ConnExecContext(ctx, conn, query, args)
+	recordQuery(ctx, query, args, time.Since(start))
+	return res, err
+}
+
+func (in *sqlInterceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := in.Interceptor.ConnQueryContext(ctx, conn, query, args)
+	recordQuery(ctx, query, args, time.Since(start))
+	return rows, err
+}
+
+func (in *sqlInterceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := in.Interceptor.StmtExecContext(ctx, stmt, query, args)
+	recordQuery(ctx, query, args, time.Since(start))
+	return res, err
+}
+
+func (in *sqlInterceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := in.Interceptor.StmtQueryContext(ctx, stmt, query, args)
+	recordQuery(ctx, query, args, time.Since(start))
+	return rows, err
+}
+
+func recordQuery(ctx context.Context, query string, args []driver.NamedValue, d time.Duration) {
+	t, ok := ctx.Value(trackerKey).(*tracker)
+	if !ok {
+		return
+	}
+
+	var values []interface{}
+	if t.keep {
+		values = make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			values = append(values, arg.Value)
+		}
+	}
+	t.recordQuery(query, values, d)
+}
@@ -0,0 +1,105 @@
+// Package usageanalytics provides an HTTP middleware that counts the API
+// requests per endpoint and day, for the detailed usage analytics.
+package usageanalytics
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/gorilla/mux"
+)
+
+// Store stores the counts of API requests.
+type Store interface {
+	IncrementUsageAnalyticsAPIRequests(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error
+}
+
+type countKey struct {
+	date   time.Time
+	method string
+	path   string
+}
+
+// Middleware is an HTTP middleware that counts the API requests. It must be
+// used with a gorilla/mux router, the API path is the template of the matched
+// route. The counts are kept in memory until they are flushed.
+type Middleware struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	counts map[countKey]int
+}
+
+// NewMiddleware creates a middleware counting the API requests.
+func NewMiddleware() *Middleware {
+	return &Middleware{
+		now:    time.Now,
+		counts: make(map[countKey]int),
+	}
+}
+
+// Handler counts the requests served by next.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path := routePath(r); path != "" {
+			k := countKey{date: m.now().UTC().Truncate(24 * time.Hour), method: r.Method, path: path}
+			m.mu.Lock()
+			m.counts[k]++
+			m.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Flush stores the counts of the requests served since the last flush. If
+// store is nil, the counts are discarded. The counts are kept for the next
+// flush if they can't be stored.
+func (m *Middleware) Flush(ctx context.Context, store Store) error {
+	m.mu.Lock()
+	counts := m.counts
+	m.counts = make(map[countKey]int)
+	m.mu.Unlock()
+
+	if store == nil || len(counts) == 0 {
+		return nil
+	}
+
+	batch := make([]fleet.UsageAnalyticsAPIRequestCount, 0, len(counts))
+	for k, n := range counts {
+		batch = append(batch, fleet.UsageAnalyticsAPIRequestCount{Date: k.date, Method: k.method, Path: k.path, Count: n})
+	}
+	if err := store.IncrementUsageAnalyticsAPIRequests(ctx, batch); err != nil {
+		m.mu.Lock()
+		for k, n := range counts {
+			m.counts[k] += n
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+var (
+	versionRegexp = regexp.MustCompile(`\{fleetversion:[^/]*\}`)
+	varRegexp     = regexp.MustCompile(`\{(\w+):[^/]*\}`)
+)
+
+// routePath returns the API path of the route matched for the request, with
+// the "_version_" placeholder for the API version and without the patterns
+// of the path variables, e.g. "/api/_version_/fleet/hosts/{id}".
+func routePath(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	tpl = versionRegexp.ReplaceAllString(tpl, "_version_")
+	return varRegexp.ReplaceAllString(tpl, "{$1}")
+}
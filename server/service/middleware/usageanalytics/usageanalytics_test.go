@@ -0,0 +1,89 @@
+package usageanalytics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type storeFunc func(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error
+
+func (f storeFunc) IncrementUsageAnalyticsAPIRequests(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error {
+	return f(ctx, counts)
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2024, 6, 22, 23, 59, 0, 0, time.UTC)
+	m := NewMiddleware()
+	m.now = func() time.Time { return now }
+
+	r := mux.NewRouter()
+	r.Use(m.Handler)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	r.Handle("/api/{fleetversion:(?:v1|latest)}/fleet/hosts/{id:[0-9]+}", ok).Methods("GET", "DELETE")
+	r.Handle("/api/{fleetversion:(?:v1|latest)}/fleet/hosts", ok).Methods("GET")
+
+	serve := func(method, path string) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	}
+	serve("GET", "/api/v1/fleet/hosts/1")
+	serve("GET", "/api/latest/fleet/hosts/2")
+	serve("DELETE", "/api/latest/fleet/hosts/2")
+	serve("GET", "/api/latest/fleet/hosts")
+	// not an API route
+	serve("GET", "/not-found")
+	now = now.Add(time.Minute)
+	serve("GET", "/api/latest/fleet/hosts")
+
+	var stored []fleet.UsageAnalyticsAPIRequestCount
+	store := storeFunc(func(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error {
+		stored = append(stored, counts...)
+		return nil
+	})
+
+	// the counts are kept if they can't be stored
+	failing := storeFunc(func(ctx context.Context, counts []fleet.UsageAnalyticsAPIRequestCount) error {
+		return errors.New("fail")
+	})
+	require.Error(t, m.Flush(context.Background(), failing))
+
+	require.NoError(t, m.Flush(context.Background(), store))
+	sort.Slice(stored, func(i, j int) bool {
+		a, b := stored[i], stored[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	day1 := time.Date(2024, 6, 22, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 6, 23, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, []fleet.UsageAnalyticsAPIRequestCount{
+		{Date: day1, Method: "GET", Path: "/api/_version_/fleet/hosts", Count: 1},
+		{Date: day1, Method: "DELETE", Path: "/api/_version_/fleet/hosts/{id}", Count: 1},
+		{Date: day1, Method: "GET", Path: "/api/_version_/fleet/hosts/{id}", Count: 2},
+		{Date: day2, Method: "GET", Path: "/api/_version_/fleet/hosts", Count: 1},
+	}, stored)
+
+	// the counts are reset once stored
+	stored = nil
+	require.NoError(t, m.Flush(context.Background(), store))
+	require.Empty(t, stored)
+
+	// the counts are discarded without a store
+	serve("GET", "/api/latest/fleet/hosts")
+	require.NoError(t, m.Flush(context.Background(), nil))
+	require.NoError(t, m.Flush(context.Background(), store))
+	require.Empty(t, stored)
+}
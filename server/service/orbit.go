@@ -130,8 +130,12 @@ func (svc *Service) EnrollOrbit(ctx context.Context, hostInfo fleet.OrbitHostInf
 		level.Info,
 	)
 
-	secret, err := svc.ds.VerifyEnrollSecret(ctx, enrollSecret)
+	secret, enrollCert, err := svc.verifyEnrollCredentials(ctx, enrollSecret, hostInfo.HardwareUUID)
 	if err != nil {
+		var authErr *fleet.AuthFailedError
+		if errors.As(err, &authErr) {
+			return "", authErr
+		}
 		if fleet.IsNotFound(err) {
 			// OK - This can happen if the following sequence of events take place:
 			// 	1. User deletes global/team enroll secret.
@@ -152,10 +156,14 @@ func (svc *Service) EnrollOrbit(ctx context.Context, hostInfo fleet.OrbitHostInf
 		return "", fleet.OrbitError{Message: "app config load failed: " + err.Error()}
 	}
 
-	_, err = svc.ds.EnrollOrbit(ctx, appConfig.MDM.EnabledAndConfigured, hostInfo, orbitNodeKey, secret.TeamID)
+	host, err := svc.ds.EnrollOrbit(ctx, appConfig.MDM.EnabledAndConfigured, hostInfo, orbitNodeKey, secret.TeamID)
 	if err != nil {
 		return "", fleet.OrbitError{Message: "failed to enroll " + err.Error()}
 	}
+	if err := svc.recordEnrollCertificate(ctx, host.ID, enrollCert); err != nil {
+		return "", fleet.OrbitError{Message: "failed to save enroll certificate " + err.Error()}
+	}
+	svc.applyEnrollSecretHostRules(ctx, host.ID, secret, appConfig)
 
 	return orbitNodeKey, nil
 }
@@ -177,6 +185,12 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		return fleet.OrbitConfig{}, fleet.OrbitError{Message: "internal error: missing host from request context"}
 	}
 
+	// orbit fetches its config frequently, use it to keep track of the host's
+	// online status. As for osquery, the seen times are batched.
+	if err := svc.task.RecordHostSeenByChannel(ctx, host.ID, fleet.HostSeenChannelOrbit); err != nil {
+		logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "record host seen by orbit"))
+	}
+
 	appConfig, err := svc.ds.AppConfig(ctx)
 	if err != nil {
 		return fleet.OrbitConfig{}, err
@@ -198,7 +212,10 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 			notifs.NeedsMDMMigration = true
 		}
 
-		if host.DiskEncryptionResetRequested != nil && *host.DiskEncryptionResetRequested {
+		// Linux hosts rotate their key via the disk encryption escrow, which
+		// clears the flag once the new key is received.
+		if host.FleetPlatform() != "linux" &&
+			host.DiskEncryptionResetRequested != nil && *host.DiskEncryptionResetRequested {
 			notifs.RotateDiskEncryptionKey = true
 
 			// Since this is an user initiated action, we disable
@@ -226,8 +243,9 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		}
 	}
 
-	// load the pending script executions for that host
-	if !appConfig.ServerSettings.ScriptsDisabled {
+	// load the pending script executions for that host, hosts that are pending
+	// enrollment approval do not run scripts until they are approved.
+	if !appConfig.ServerSettings.ScriptsDisabled && !host.PendingApproval {
 		pending, err := svc.ds.ListPendingHostScriptExecutions(ctx, host.ID)
 		if err != nil {
 			return fleet.OrbitConfig{}, err
@@ -241,6 +259,58 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		}
 	}
 
+	// load the pending log collections for that host
+	pendingLogs, err := svc.ds.ListPendingHostLogCollections(ctx, host.ID)
+	if err != nil {
+		return fleet.OrbitConfig{}, err
+	}
+	if len(pendingLogs) > 0 {
+		execIDs := make([]string, 0, len(pendingLogs))
+		for _, p := range pendingLogs {
+			execIDs = append(execIDs, p.ExecutionID)
+		}
+		notifs.PendingLogCollectionIDs = execIDs
+	}
+
+	// load the pending file retrievals for that host
+	pendingFiles, err := svc.ds.ListPendingHostFileRetrievals(ctx, host.ID)
+	if err != nil {
+		return fleet.OrbitConfig{}, err
+	}
+	if len(pendingFiles) > 0 {
+		execIDs := make([]string, 0, len(pendingFiles))
+		for _, p := range pendingFiles {
+			execIDs = append(execIDs, p.ExecutionID)
+		}
+		notifs.PendingFileRetrievalIDs = execIDs
+	}
+
+	// load the pending pprof captures for that host
+	pendingPprof, err := svc.ds.ListPendingHostPprofCaptures(ctx, host.ID)
+	if err != nil {
+		return fleet.OrbitConfig{}, err
+	}
+	if len(pendingPprof) > 0 {
+		execIDs := make([]string, 0, len(pendingPprof))
+		for _, p := range pendingPprof {
+			execIDs = append(execIDs, p.ExecutionID)
+		}
+		notifs.PendingPprofCaptureIDs = execIDs
+	}
+
+	// load the pending remote queries for that host
+	pendingQueries, err := svc.ds.ListPendingHostRemoteQueries(ctx, host.ID)
+	if err != nil {
+		return fleet.OrbitConfig{}, err
+	}
+	if len(pendingQueries) > 0 {
+		execIDs := make([]string, 0, len(pendingQueries))
+		for _, p := range pendingQueries {
+			execIDs = append(execIDs, p.ExecutionID)
+		}
+		notifs.PendingRemoteQueryIDs = execIDs
+	}
+
 	// team ID is not nil, get team specific flags and options
 	if host.TeamID != nil {
 		teamAgentOptions, err := svc.ds.TeamAgentOptions(ctx, *host.TeamID)
@@ -282,7 +352,7 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 			}
 
 			if requiresNudge {
-				nudgeConfig, err = fleet.NewNudgeConfig(mdmConfig.MacOSUpdates)
+				nudgeConfig, err = fleet.NewNudgeConfig(mdmConfig.MacOSUpdates, fleet.HostLocation(host.Timezone))
 				if err != nil {
 					return fleet.OrbitConfig{}, err
 				}
@@ -294,6 +364,17 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 			notifs.EnforceBitLockerEncryption = true
 		}
 
+		if mdmConfig.EnableDiskEncryption &&
+			svc.config.Server.PrivateKey != "" &&
+			host.IsEligibleForLinuxDiskEncryptionEscrow() {
+			notifs.RunLinuxDiskEncryptionEscrow = true
+		}
+
+		if mdmConfig.EnableDiskEncryption &&
+			host.IsEligibleForLinuxDiskEncryptionEnforcement() {
+			notifs.EnforceLinuxDiskEncryption = true
+		}
+
 		var updateChannels *fleet.OrbitUpdateChannels
 		if len(opts.UpdateChannels) > 0 {
 			var uc fleet.OrbitUpdateChannels
@@ -303,12 +384,23 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 			updateChannels = &uc
 		}
 
+		var windowsService *fleet.OrbitWindowsServiceConfig
+		if len(opts.WindowsService) > 0 && host.Platform == "windows" {
+			var ws fleet.OrbitWindowsServiceConfig
+			if err := json.Unmarshal(opts.WindowsService, &ws); err != nil {
+				return fleet.OrbitConfig{}, err
+			}
+			windowsService = &ws
+		}
+
 		return fleet.OrbitConfig{
 			Flags:          opts.CommandLineStartUpFlags,
 			Extensions:     extensionsFiltered,
 			Notifications:  notifs,
 			NudgeConfig:    nudgeConfig,
 			UpdateChannels: updateChannels,
+			WindowsService: windowsService,
+			FeatureFlags:   fleet.OrbitFeatureFlagsForHost(appConfig.OrbitFeatureFlags, host),
 		}, nil
 	}
 
@@ -341,7 +433,7 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		}
 
 		if requiresNudge {
-			nudgeConfig, err = fleet.NewNudgeConfig(appConfig.MDM.MacOSUpdates)
+			nudgeConfig, err = fleet.NewNudgeConfig(appConfig.MDM.MacOSUpdates, fleet.HostLocation(host.Timezone))
 			if err != nil {
 				return fleet.OrbitConfig{}, err
 
@@ -355,6 +447,17 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		notifs.EnforceBitLockerEncryption = true
 	}
 
+	if appConfig.MDM.EnableDiskEncryption.Value &&
+		svc.config.Server.PrivateKey != "" &&
+		host.IsEligibleForLinuxDiskEncryptionEscrow() {
+		notifs.RunLinuxDiskEncryptionEscrow = true
+	}
+
+	if appConfig.MDM.EnableDiskEncryption.Value &&
+		host.IsEligibleForLinuxDiskEncryptionEnforcement() {
+		notifs.EnforceLinuxDiskEncryption = true
+	}
+
 	var updateChannels *fleet.OrbitUpdateChannels
 	if len(opts.UpdateChannels) > 0 {
 		var uc fleet.OrbitUpdateChannels
@@ -364,12 +467,23 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		updateChannels = &uc
 	}
 
+	var windowsService *fleet.OrbitWindowsServiceConfig
+	if len(opts.WindowsService) > 0 && host.Platform == "windows" {
+		var ws fleet.OrbitWindowsServiceConfig
+		if err := json.Unmarshal(opts.WindowsService, &ws); err != nil {
+			return fleet.OrbitConfig{}, err
+		}
+		windowsService = &ws
+	}
+
 	return fleet.OrbitConfig{
 		Flags:          opts.CommandLineStartUpFlags,
 		Extensions:     extensionsFiltered,
 		Notifications:  notifs,
 		NudgeConfig:    nudgeConfig,
 		UpdateChannels: updateChannels,
+		WindowsService: windowsService,
+		FeatureFlags:   fleet.OrbitFeatureFlagsForHost(appConfig.OrbitFeatureFlags, host),
 	}, nil
 }
 
@@ -545,6 +659,15 @@ func (svc *Service) GetHostScript(ctx context.Context, execID string) (*fleet.Ho
 	if script.HostID != host.ID {
 		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "no script found for this host")
 	}
+	if host.PendingApproval {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "host is pending enrollment approval")
+	}
+
+	// the host receives the script with the secret variables expanded
+	script.ScriptContents, err = expandSecretVariables(ctx, svc.ds, script.ScriptContents, false)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "expand secret variables")
+	}
 	return script, nil
 }
 
@@ -595,6 +718,20 @@ func (svc *Service) SaveHostScriptResult(ctx context.Context, result *fleet.Host
 
 	// always use the authenticated host's ID as host_id
 	result.HostID = host.ID
+
+	// mask the values of the secret variables used by the script from its
+	// output so that they are not stored nor displayed.
+	script, err := svc.ds.GetHostScriptExecutionResult(ctx, result.ExecutionID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get host script execution")
+	}
+	if script != nil && script.HostID == host.ID {
+		result.Output, err = redactSecretVariables(ctx, svc.ds, script.ScriptContents, result.Output)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "redact secret variables from script output")
+		}
+	}
+
 	hsr, err := svc.ds.SetHostScriptExecutionResult(ctx, result)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "save host script result")
@@ -636,114 +773,897 @@ func (svc *Service) SaveHostScriptResult(ctx context.Context, result *fleet.Host
 }
 
 /////////////////////////////////////////////////////////////////////////////////
-// Post Orbit device mapping (custom email)
+// Get Orbit pending log collection request
 /////////////////////////////////////////////////////////////////////////////////
 
-type orbitPutDeviceMappingRequest struct {
+type orbitGetLogCollectionRequest struct {
 	OrbitNodeKey string `json:"orbit_node_key"`
-	Email        string `json:"email"`
+	ExecutionID  string `json:"execution_id"`
 }
 
 // interface implementation required by the OrbitClient
-func (r *orbitPutDeviceMappingRequest) setOrbitNodeKey(nodeKey string) {
+func (r *orbitGetLogCollectionRequest) setOrbitNodeKey(nodeKey string) {
 	r.OrbitNodeKey = nodeKey
 }
 
 // interface implementation required by orbit authentication
-func (r *orbitPutDeviceMappingRequest) orbitHostNodeKey() string {
+func (r *orbitGetLogCollectionRequest) orbitHostNodeKey() string {
 	return r.OrbitNodeKey
 }
 
-type orbitPutDeviceMappingResponse struct {
+type orbitGetLogCollectionResponse struct {
 	Err error `json:"error,omitempty"`
+	*fleet.HostLogCollection
 }
 
-func (r orbitPutDeviceMappingResponse) error() error { return r.Err }
+func (r orbitGetLogCollectionResponse) error() error { return r.Err }
 
-func putOrbitDeviceMappingEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
-	req := request.(*orbitPutDeviceMappingRequest)
+func getOrbitLogCollectionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitGetLogCollectionRequest)
+	lc, err := svc.GetHostLogCollectionRequest(ctx, req.ExecutionID)
+	if err != nil {
+		return orbitGetLogCollectionResponse{Err: err}, nil
+	}
+	return orbitGetLogCollectionResponse{HostLogCollection: lc}, nil
+}
+
+func (svc *Service) GetHostLogCollectionRequest(ctx context.Context, execID string) (*fleet.HostLogCollection, error) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
 
 	host, ok := hostctx.FromContext(ctx)
 	if !ok {
-		err := newOsqueryError("internal error: missing host from request context")
-		return orbitPutDeviceMappingResponse{Err: err}, nil
+		return nil, fleet.OrbitError{Message: "internal error: missing host from request context"}
 	}
 
-	_, err := svc.SetCustomHostDeviceMapping(ctx, host.ID, req.Email)
-	return orbitPutDeviceMappingResponse{Err: err}, nil
+	lc, err := svc.ds.GetHostLogCollection(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+	// ensure it cannot get access to a different host's log collection
+	if lc.HostID != host.ID {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "no log collection found for this host")
+	}
+	return lc, nil
 }
 
 /////////////////////////////////////////////////////////////////////////////////
-// Post Orbit disk encryption key
+// Post Orbit log collection result
 /////////////////////////////////////////////////////////////////////////////////
 
-type orbitPostDiskEncryptionKeyRequest struct {
-	OrbitNodeKey  string `json:"orbit_node_key"`
-	EncryptionKey []byte `json:"encryption_key"`
-	ClientError   string `json:"client_error"`
+type orbitPostLogCollectionResultRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostLogCollectionResultPayload
 }
 
 // interface implementation required by the OrbitClient
-func (r *orbitPostDiskEncryptionKeyRequest) setOrbitNodeKey(nodeKey string) {
+func (r *orbitPostLogCollectionResultRequest) setOrbitNodeKey(nodeKey string) {
 	r.OrbitNodeKey = nodeKey
 }
 
 // interface implementation required by orbit authentication
-func (r *orbitPostDiskEncryptionKeyRequest) orbitHostNodeKey() string {
+func (r *orbitPostLogCollectionResultRequest) orbitHostNodeKey() string {
 	return r.OrbitNodeKey
 }
 
-type orbitPostDiskEncryptionKeyResponse struct {
+type orbitPostLogCollectionResultResponse struct {
 	Err error `json:"error,omitempty"`
 }
 
-func (r orbitPostDiskEncryptionKeyResponse) error() error { return r.Err }
-func (r orbitPostDiskEncryptionKeyResponse) Status() int  { return http.StatusNoContent }
+func (r orbitPostLogCollectionResultResponse) error() error { return r.Err }
 
-func postOrbitDiskEncryptionKeyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
-	req := request.(*orbitPostDiskEncryptionKeyRequest)
-	if err := svc.SetOrUpdateDiskEncryptionKey(ctx, string(req.EncryptionKey), req.ClientError); err != nil {
-		return orbitPostDiskEncryptionKeyResponse{Err: err}, nil
+func postOrbitLogCollectionResultEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostLogCollectionResultRequest)
+	if err := svc.SaveHostLogCollectionResult(ctx, req.HostLogCollectionResultPayload); err != nil {
+		return orbitPostLogCollectionResultResponse{Err: err}, nil
 	}
-	return orbitPostDiskEncryptionKeyResponse{}, nil
+	return orbitPostLogCollectionResultResponse{}, nil
 }
 
-func (svc *Service) SetOrUpdateDiskEncryptionKey(ctx context.Context, encryptionKey, clientError string) error {
+func (svc *Service) SaveHostLogCollectionResult(ctx context.Context, result *fleet.HostLogCollectionResultPayload) error {
 	// this is not a user-authenticated endpoint
 	svc.authz.SkipAuthorization(ctx)
 
 	host, ok := hostctx.FromContext(ctx)
 	if !ok {
-		return newOsqueryError("internal error: missing host from request context")
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
 	}
-	if !host.MDMInfo.IsFleetEnrolled() {
-		return badRequest("host is not enrolled with fleet")
+	if result == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing log collection result"}, "save host log collection result")
 	}
 
-	var (
-		encryptedEncryptionKey string
-		decryptable            *bool
-	)
+	// always use the authenticated host's ID as host_id
+	result.HostID = host.ID
 
-	// only set the encryption key if there was no client error
-	if clientError == "" && encryptionKey != "" {
-		wstepCert, _, _, err := svc.config.MDM.MicrosoftWSTEP()
-		if err != nil {
-			// should never return an error because the WSTEP is first parsed and
-			// cached at the start of the fleet serve process.
-			return ctxerr.Wrap(ctx, err, "get WSTEP certificate")
-		}
-		enc, err := microsoft_mdm.Encrypt(encryptionKey, wstepCert.Leaf)
-		if err != nil {
-			return ctxerr.Wrap(ctx, err, "encrypt the key with WSTEP certificate")
-		}
-		encryptedEncryptionKey = enc
-		decryptable = ptr.Bool(true)
+	if len(result.Data) > fleet.MaxHostLogCollectionSize {
+		// store the failure so that the request is not sent again to the host
+		result.Error = fmt.Sprintf("collected logs are too large: %d bytes (maximum is %d bytes)", len(result.Data), fleet.MaxHostLogCollectionSize)
+		result.Data = nil
+	}
+	if result.Error == "" && len(result.Data) == 0 {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing collected logs"}, "save host log collection result")
 	}
 
-	if err := svc.ds.SetOrUpdateHostDiskEncryptionKey(ctx, host.ID, encryptedEncryptionKey, clientError, decryptable); err != nil {
-		return ctxerr.Wrap(ctx, err, "set or update disk encryption key")
+	if _, err := svc.ds.SetHostLogCollectionResult(ctx, result); err != nil {
+		return ctxerr.Wrap(ctx, err, "save host log collection result")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get Orbit pending file retrieval request
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitGetFileRetrievalRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	ExecutionID  string `json:"execution_id"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitGetFileRetrievalRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitGetFileRetrievalRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitGetFileRetrievalResponse struct {
+	Err error `json:"error,omitempty"`
+	*fleet.HostFileRetrieval
+}
+
+func (r orbitGetFileRetrievalResponse) error() error { return r.Err }
+
+func getOrbitFileRetrievalEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitGetFileRetrievalRequest)
+	fr, err := svc.GetHostFileRetrievalRequest(ctx, req.ExecutionID)
+	if err != nil {
+		return orbitGetFileRetrievalResponse{Err: err}, nil
+	}
+	return orbitGetFileRetrievalResponse{HostFileRetrieval: fr}, nil
+}
+
+func (svc *Service) GetHostFileRetrievalRequest(ctx context.Context, execID string) (*fleet.HostFileRetrieval, error) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+
+	fr, err := svc.ds.GetHostFileRetrieval(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+	// ensure it cannot get access to a different host's file retrieval
+	if fr.HostID != host.ID {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "no file retrieval found for this host")
+	}
+
+	settings, err := svc.fileRetrievalSettings(ctx, host.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	fr.AllowedPaths = settings.AllowedPaths
+	return fr, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit file retrieval result
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostFileRetrievalResultRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostFileRetrievalResultPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostFileRetrievalResultRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostFileRetrievalResultRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostFileRetrievalResultResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostFileRetrievalResultResponse) error() error { return r.Err }
+
+func postOrbitFileRetrievalResultEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostFileRetrievalResultRequest)
+	if err := svc.SaveHostFileRetrievalResult(ctx, req.HostFileRetrievalResultPayload); err != nil {
+		return orbitPostFileRetrievalResultResponse{Err: err}, nil
+	}
+	return orbitPostFileRetrievalResultResponse{}, nil
+}
+
+func (svc *Service) SaveHostFileRetrievalResult(ctx context.Context, result *fleet.HostFileRetrievalResultPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+	if result == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing file retrieval result"}, "save host file retrieval result")
+	}
+
+	// always use the authenticated host's ID as host_id
+	result.HostID = host.ID
+
+	if len(result.Data) > fleet.MaxHostFileRetrievalSize {
+		// store the failure so that the request is not sent again to the host
+		result.Error = fmt.Sprintf("file is too large: %d bytes (maximum is %d bytes)", len(result.Data), fleet.MaxHostFileRetrievalSize)
+		result.Data = nil
+	}
+
+	if _, err := svc.ds.SetHostFileRetrievalResult(ctx, result); err != nil {
+		return ctxerr.Wrap(ctx, err, "save host file retrieval result")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get Orbit pending pprof capture request
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitGetPprofCaptureRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	ExecutionID  string `json:"execution_id"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitGetPprofCaptureRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitGetPprofCaptureRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitGetPprofCaptureResponse struct {
+	Err error `json:"error,omitempty"`
+	*fleet.HostPprofCapture
+}
+
+func (r orbitGetPprofCaptureResponse) error() error { return r.Err }
+
+func getOrbitPprofCaptureEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitGetPprofCaptureRequest)
+	pc, err := svc.GetHostPprofCaptureRequest(ctx, req.ExecutionID)
+	if err != nil {
+		return orbitGetPprofCaptureResponse{Err: err}, nil
+	}
+	return orbitGetPprofCaptureResponse{HostPprofCapture: pc}, nil
+}
+
+func (svc *Service) GetHostPprofCaptureRequest(ctx context.Context, execID string) (*fleet.HostPprofCapture, error) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, fleet.OrbitError{Message: "internal error: missing host from request context"}
 	}
 
+	pc, err := svc.ds.GetHostPprofCapture(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+	// ensure it cannot get access to a different host's pprof capture
+	if pc.HostID != host.ID {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "no pprof capture found for this host")
+	}
+	return pc, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit pprof capture result
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostPprofCaptureResultRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostPprofCaptureResultPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostPprofCaptureResultRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostPprofCaptureResultRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostPprofCaptureResultResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostPprofCaptureResultResponse) error() error { return r.Err }
+
+func postOrbitPprofCaptureResultEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostPprofCaptureResultRequest)
+	if err := svc.SaveHostPprofCaptureResult(ctx, req.HostPprofCaptureResultPayload); err != nil {
+		return orbitPostPprofCaptureResultResponse{Err: err}, nil
+	}
+	return orbitPostPprofCaptureResultResponse{}, nil
+}
+
+func (svc *Service) SaveHostPprofCaptureResult(ctx context.Context, result *fleet.HostPprofCaptureResultPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+	if result == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing pprof capture result"}, "save host pprof capture result")
+	}
+
+	// always use the authenticated host's ID as host_id
+	result.HostID = host.ID
+
+	if len(result.Data) > fleet.MaxHostPprofCaptureSize {
+		// store the failure so that the request is not sent again to the host
+		result.Error = fmt.Sprintf("profile is too large: %d bytes (maximum is %d bytes)", len(result.Data), fleet.MaxHostPprofCaptureSize)
+		result.Data = nil
+	}
+
+	if _, err := svc.ds.SetHostPprofCaptureResult(ctx, result); err != nil {
+		return ctxerr.Wrap(ctx, err, "save host pprof capture result")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit tamper event
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostTamperEventRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostTamperEventPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostTamperEventRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostTamperEventRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostTamperEventResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostTamperEventResponse) error() error { return r.Err }
+
+func postOrbitTamperEventEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostTamperEventRequest)
+	if err := svc.SaveHostTamperEvent(ctx, req.HostTamperEventPayload); err != nil {
+		return orbitPostTamperEventResponse{Err: err}, nil
+	}
+	return orbitPostTamperEventResponse{}, nil
+}
+
+func (svc *Service) SaveHostTamperEvent(ctx context.Context, event *fleet.HostTamperEventPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+	if event == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing tamper event"}, "save host tamper event")
+	}
+	if !event.Type.IsValid() {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: fmt.Sprintf("invalid tamper event type: %q", event.Type)}, "save host tamper event")
+	}
+	if len(event.Details) > fleet.MaxHostTamperEventDetailsLength {
+		event.Details = event.Details[:fleet.MaxHostTamperEventDetailsLength]
+	}
+	if event.DetectedAt.IsZero() {
+		event.DetectedAt = svc.clock.Now().UTC()
+	}
+
+	if err := svc.ds.NewActivity(ctx, nil, fleet.ActivityTypeHostTamperDetected{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		EventType:       event.Type,
+		Details:         event.Details,
+		DetectedAt:      event.DetectedAt,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for host tamper event")
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if err := sendTamperEventWebhook(ctx, appConfig.WebhookSettings.TamperEventsWebhook, host, event); err != nil {
+		// the event is recorded as an activity, do not fail the request so
+		// that fleetd doesn't report it again.
+		level.Error(svc.logger).Log("msg", "send tamper event webhook", "host_id", host.ID, "err", err)
+		ctxerr.Handle(ctx, err)
+	}
+	return nil
+}
+
+// sendTamperEventWebhook sends the tamper event to the tamper events webhook,
+// if enabled.
+func sendTamperEventWebhook(ctx context.Context, settings fleet.TamperEventsWebhookSettings, host *fleet.Host, event *fleet.HostTamperEventPayload) error {
+	if !settings.Enable {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"text": fmt.Sprintf("Tamper event %q detected on host %s. "+
+			"You've been sent this message because the Tamper events webhook is enabled in your Fleet instance.", event.Type, host.DisplayName()),
+		"data": map[string]interface{}{
+			"host_id":           host.ID,
+			"host_display_name": host.DisplayName(),
+			"host_serial":       host.HardwareSerial,
+			"event_type":        event.Type,
+			"details":           event.Details,
+			"detected_at":       event.DetectedAt,
+		},
+	}
+	if err := server.PostJSONWithTimeout(ctx, settings.DestinationURL, &payload); err != nil {
+		return ctxerr.Wrapf(ctx, err, "posting to %s", settings.DestinationURL)
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit service recovery
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostServiceRecoveryRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostServiceRecoveryPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostServiceRecoveryRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostServiceRecoveryRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostServiceRecoveryResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostServiceRecoveryResponse) error() error { return r.Err }
+
+func postOrbitServiceRecoveryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostServiceRecoveryRequest)
+	if err := svc.SaveHostServiceRecovery(ctx, req.HostServiceRecoveryPayload); err != nil {
+		return orbitPostServiceRecoveryResponse{Err: err}, nil
+	}
+	return orbitPostServiceRecoveryResponse{}, nil
+}
+
+func (svc *Service) SaveHostServiceRecovery(ctx context.Context, recovery *fleet.HostServiceRecoveryPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+	if recovery == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing service recovery"}, "save host service recovery")
+	}
+	if !recovery.Reason.IsValid() {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: fmt.Sprintf("invalid service recovery reason: %q", recovery.Reason)}, "save host service recovery")
+	}
+	if len(recovery.Details) > fleet.MaxHostServiceRecoveryDetailsLength {
+		recovery.Details = recovery.Details[:fleet.MaxHostServiceRecoveryDetailsLength]
+	}
+	if recovery.RecoveredAt.IsZero() {
+		recovery.RecoveredAt = svc.clock.Now().UTC()
+	}
+
+	if err := svc.ds.NewActivity(ctx, nil, fleet.ActivityTypeFleetdServiceRecovered{
+		HostID:          host.ID,
+		HostDisplayName: host.DisplayName(),
+		Reason:          recovery.Reason,
+		Details:         recovery.Details,
+		RecoveredAt:     recovery.RecoveredAt,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for host service recovery")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get Orbit pending remote query request
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitGetRemoteQueryRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	ExecutionID  string `json:"execution_id"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitGetRemoteQueryRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitGetRemoteQueryRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitGetRemoteQueryResponse struct {
+	Err error `json:"error,omitempty"`
+	*fleet.HostRemoteQuery
+}
+
+func (r orbitGetRemoteQueryResponse) error() error { return r.Err }
+
+func getOrbitRemoteQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitGetRemoteQueryRequest)
+	rq, err := svc.GetHostRemoteQueryRequest(ctx, req.ExecutionID)
+	if err != nil {
+		return orbitGetRemoteQueryResponse{Err: err}, nil
+	}
+	return orbitGetRemoteQueryResponse{HostRemoteQuery: rq}, nil
+}
+
+func (svc *Service) GetHostRemoteQueryRequest(ctx context.Context, execID string) (*fleet.HostRemoteQuery, error) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+
+	rq, err := svc.ds.GetHostRemoteQuery(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+	// ensure it cannot get access to a different host's remote query
+	if rq.HostID != host.ID {
+		return nil, ctxerr.Wrap(ctx, newNotFoundError(), "no remote query found for this host")
+	}
+	// the host only needs the query to run, not the results
+	rq.Rows = nil
+	return rq, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit remote query result
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostRemoteQueryResultRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	*fleet.HostRemoteQueryResultPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostRemoteQueryResultRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostRemoteQueryResultRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostRemoteQueryResultResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostRemoteQueryResultResponse) error() error { return r.Err }
+
+func postOrbitRemoteQueryResultEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostRemoteQueryResultRequest)
+	if err := svc.SaveHostRemoteQueryResult(ctx, req.HostRemoteQueryResultPayload); err != nil {
+		return orbitPostRemoteQueryResultResponse{Err: err}, nil
+	}
+	return orbitPostRemoteQueryResultResponse{}, nil
+}
+
+func (svc *Service) SaveHostRemoteQueryResult(ctx context.Context, result *fleet.HostRemoteQueryResultPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+	if result == nil {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "missing remote query result"}, "save host remote query result")
+	}
+
+	// always use the authenticated host's ID as host_id
+	result.HostID = host.ID
+
+	// store the failures so that the request is not sent again to the host
+	if len(result.Rows) > fleet.MaxHostRemoteQueryRows {
+		result.Error = fmt.Sprintf("too many rows: %d (maximum is %d)", len(result.Rows), fleet.MaxHostRemoteQueryRows)
+		result.Rows = nil
+	}
+	if b, err := json.Marshal(result.Rows); err == nil && len(b) > fleet.MaxHostRemoteQueryResultSize {
+		result.Error = fmt.Sprintf("results are too large: %d bytes (maximum is %d bytes)", len(b), fleet.MaxHostRemoteQueryResultSize)
+		result.Rows = nil
+	}
+
+	if _, err := svc.ds.SetHostRemoteQueryResult(ctx, result); err != nil {
+		return ctxerr.Wrap(ctx, err, "save host remote query result")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit device mapping (custom email)
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPutDeviceMappingRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	Email        string `json:"email"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPutDeviceMappingRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPutDeviceMappingRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPutDeviceMappingResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPutDeviceMappingResponse) error() error { return r.Err }
+
+func putOrbitDeviceMappingEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPutDeviceMappingRequest)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		err := newOsqueryError("internal error: missing host from request context")
+		return orbitPutDeviceMappingResponse{Err: err}, nil
+	}
+
+	_, err := svc.SetCustomHostDeviceMapping(ctx, host.ID, req.Email)
+	return orbitPutDeviceMappingResponse{Err: err}, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit disk encryption key
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostDiskEncryptionKeyRequest struct {
+	OrbitNodeKey  string `json:"orbit_node_key"`
+	EncryptionKey []byte `json:"encryption_key"`
+	ClientError   string `json:"client_error"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostDiskEncryptionKeyRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostDiskEncryptionKeyRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostDiskEncryptionKeyResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostDiskEncryptionKeyResponse) error() error { return r.Err }
+func (r orbitPostDiskEncryptionKeyResponse) Status() int  { return http.StatusNoContent }
+
+func postOrbitDiskEncryptionKeyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostDiskEncryptionKeyRequest)
+	if err := svc.SetOrUpdateDiskEncryptionKey(ctx, string(req.EncryptionKey), req.ClientError); err != nil {
+		return orbitPostDiskEncryptionKeyResponse{Err: err}, nil
+	}
+	return orbitPostDiskEncryptionKeyResponse{}, nil
+}
+
+func (svc *Service) SetOrUpdateDiskEncryptionKey(ctx context.Context, encryptionKey, clientError string) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return newOsqueryError("internal error: missing host from request context")
+	}
+	if !host.MDMInfo.IsFleetEnrolled() {
+		return badRequest("host is not enrolled with fleet")
+	}
+
+	var (
+		encryptedEncryptionKey string
+		decryptable            *bool
+	)
+
+	// only set the encryption key if there was no client error
+	if clientError == "" && encryptionKey != "" {
+		wstepCert, _, _, err := svc.config.MDM.MicrosoftWSTEP()
+		if err != nil {
+			// should never return an error because the WSTEP is first parsed and
+			// cached at the start of the fleet serve process.
+			return ctxerr.Wrap(ctx, err, "get WSTEP certificate")
+		}
+		enc, err := microsoft_mdm.Encrypt(encryptionKey, wstepCert.Leaf)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "encrypt the key with WSTEP certificate")
+		}
+		encryptedEncryptionKey = enc
+		decryptable = ptr.Bool(true)
+	}
+
+	if err := svc.ds.SetOrUpdateHostDiskEncryptionKey(ctx, host.ID, encryptedEncryptionKey, clientError, decryptable); err != nil {
+		return ctxerr.Wrap(ctx, err, "set or update disk encryption key")
+	}
+
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit LUKS data
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostLUKSRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	Passphrase   string `json:"passphrase"`
+	KeySlot      *uint  `json:"key_slot"`
+	ClientError  string `json:"client_error"`
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostLUKSRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostLUKSRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostLUKSResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostLUKSResponse) error() error { return r.Err }
+func (r orbitPostLUKSResponse) Status() int  { return http.StatusNoContent }
+
+func postOrbitLUKSEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostLUKSRequest)
+	if err := svc.EscrowLinuxDiskEncryptionKey(ctx, req.Passphrase, req.KeySlot, req.ClientError); err != nil {
+		return orbitPostLUKSResponse{Err: err}, nil
+	}
+	return orbitPostLUKSResponse{}, nil
+}
+
+// maxLUKSKeySlot is the highest key slot of a LUKS2 header.
+const maxLUKSKeySlot = 31
+
+func (svc *Service) EscrowLinuxDiskEncryptionKey(ctx context.Context, passphrase string, keySlot *uint, clientError string) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return newOsqueryError("internal error: missing host from request context")
+	}
+	if host.FleetPlatform() != "linux" {
+		return badRequest("host is not a Linux host")
+	}
+
+	if clientError == "" {
+		if passphrase == "" {
+			return badRequest("passphrase is required")
+		}
+		if keySlot == nil || *keySlot > maxLUKSKeySlot {
+			return badRequest(fmt.Sprintf("key_slot must be between 0 and %d", maxLUKSKeySlot))
+		}
+	}
+	if len(clientError) > 255 {
+		// the column is a varchar(255)
+		clientError = clientError[:255]
+	}
+
+	// the passphrase is encrypted with the server private key, it cannot be
+	// stored otherwise (orbit is not notified to escrow it in that case).
+	if svc.config.Server.PrivateKey == "" {
+		return ctxerr.New(ctx, "server private key is required to escrow the LUKS passphrase")
+	}
+
+	if err := svc.ds.SaveLUKSDiskEncryptionKey(ctx, host.ID, passphrase, keySlot, clientError); err != nil {
+		return ctxerr.Wrap(ctx, err, "save LUKS disk encryption key")
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit Linux disk encryption enforcement status
+/////////////////////////////////////////////////////////////////////////////////
+
+type orbitPostLinuxDiskEncryptionEnforcementRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	fleet.OrbitLinuxDiskEncryptionEnforcementPayload
+}
+
+// interface implementation required by the OrbitClient
+func (r *orbitPostLinuxDiskEncryptionEnforcementRequest) setOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+// interface implementation required by orbit authentication
+func (r *orbitPostLinuxDiskEncryptionEnforcementRequest) orbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type orbitPostLinuxDiskEncryptionEnforcementResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r orbitPostLinuxDiskEncryptionEnforcementResponse) error() error { return r.Err }
+func (r orbitPostLinuxDiskEncryptionEnforcementResponse) Status() int  { return http.StatusNoContent }
+
+func postOrbitLinuxDiskEncryptionEnforcementEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*orbitPostLinuxDiskEncryptionEnforcementRequest)
+	if err := svc.SetLinuxDiskEncryptionEnforcementStatus(ctx, req.OrbitLinuxDiskEncryptionEnforcementPayload); err != nil {
+		return orbitPostLinuxDiskEncryptionEnforcementResponse{Err: err}, nil
+	}
+	return orbitPostLinuxDiskEncryptionEnforcementResponse{}, nil
+}
+
+// maxLinuxDiskEncryptionEnforcementDetail is the maximum length of the detail
+// reported by orbit, longer details are truncated.
+const maxLinuxDiskEncryptionEnforcementDetail = 1024
+
+func (svc *Service) SetLinuxDiskEncryptionEnforcementStatus(ctx context.Context, payload fleet.OrbitLinuxDiskEncryptionEnforcementPayload) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return newOsqueryError("internal error: missing host from request context")
+	}
+	if host.FleetPlatform() != "linux" {
+		return badRequest("host is not a Linux host")
+	}
+	if !payload.Status.IsValid() {
+		return badRequest(fmt.Sprintf("invalid status %q", payload.Status))
+	}
+
+	detail := payload.Detail
+	if len(detail) > maxLinuxDiskEncryptionEnforcementDetail {
+		detail = detail[:maxLinuxDiskEncryptionEnforcementDetail]
+	}
+
+	if err := svc.ds.SetHostLinuxDiskEncryptionEnforcement(ctx, host.ID, payload.Status, detail); err != nil {
+		return ctxerr.Wrap(ctx, err, "set host linux disk encryption enforcement")
+	}
 	return nil
 }
@@ -230,6 +230,130 @@ func (oc *OrbitClient) SaveHostScriptResult(result *fleet.HostScriptResultPayloa
 	return nil
 }
 
+// GetHostLogCollection returns the log collection request identified by
+// execID.
+func (oc *OrbitClient) GetHostLogCollection(execID string) (*fleet.HostLogCollection, error) {
+	verb, path := "POST", "/api/fleet/orbit/log_collections/request"
+	var resp orbitGetLogCollectionResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitGetLogCollectionRequest{
+		ExecutionID: execID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.HostLogCollection, nil
+}
+
+// SaveHostLogCollectionResult uploads the logs collected on this host.
+func (oc *OrbitClient) SaveHostLogCollectionResult(result *fleet.HostLogCollectionResultPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/log_collections/result"
+	var resp orbitPostLogCollectionResultResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostLogCollectionResultRequest{
+		HostLogCollectionResultPayload: result,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetHostFileRetrieval returns the file retrieval request identified by
+// execID.
+func (oc *OrbitClient) GetHostFileRetrieval(execID string) (*fleet.HostFileRetrieval, error) {
+	verb, path := "POST", "/api/fleet/orbit/file_retrievals/request"
+	var resp orbitGetFileRetrievalResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitGetFileRetrievalRequest{
+		ExecutionID: execID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.HostFileRetrieval, nil
+}
+
+// SaveHostFileRetrievalResult uploads the file retrieved on this host.
+func (oc *OrbitClient) SaveHostFileRetrievalResult(result *fleet.HostFileRetrievalResultPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/file_retrievals/result"
+	var resp orbitPostFileRetrievalResultResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostFileRetrievalResultRequest{
+		HostFileRetrievalResultPayload: result,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetHostPprofCapture returns the pprof capture request identified by execID.
+func (oc *OrbitClient) GetHostPprofCapture(execID string) (*fleet.HostPprofCapture, error) {
+	verb, path := "POST", "/api/fleet/orbit/pprof_captures/request"
+	var resp orbitGetPprofCaptureResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitGetPprofCaptureRequest{
+		ExecutionID: execID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.HostPprofCapture, nil
+}
+
+// SaveHostPprofCaptureResult uploads the profile captured on this host.
+func (oc *OrbitClient) SaveHostPprofCaptureResult(result *fleet.HostPprofCaptureResultPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/pprof_captures/result"
+	var resp orbitPostPprofCaptureResultResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostPprofCaptureResultRequest{
+		HostPprofCaptureResultPayload: result,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReportTamperEvent reports a tamper event detected on this host.
+func (oc *OrbitClient) ReportTamperEvent(event *fleet.HostTamperEventPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/tamper_events"
+	var resp orbitPostTamperEventResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostTamperEventRequest{
+		HostTamperEventPayload: event,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReportServiceRecovery reports that the recovery actions of the fleetd
+// service fired on this host.
+func (oc *OrbitClient) ReportServiceRecovery(recovery *fleet.HostServiceRecoveryPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/service_recoveries"
+	var resp orbitPostServiceRecoveryResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostServiceRecoveryRequest{
+		HostServiceRecoveryPayload: recovery,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetHostRemoteQuery returns the remote query request identified by execID.
+func (oc *OrbitClient) GetHostRemoteQuery(execID string) (*fleet.HostRemoteQuery, error) {
+	verb, path := "POST", "/api/fleet/orbit/remote_queries/request"
+	var resp orbitGetRemoteQueryResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitGetRemoteQueryRequest{
+		ExecutionID: execID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.HostRemoteQuery, nil
+}
+
+// SaveHostRemoteQueryResult sends the results of a remote query run on this
+// host.
+func (oc *OrbitClient) SaveHostRemoteQueryResult(result *fleet.HostRemoteQueryResultPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/remote_queries/result"
+	var resp orbitPostRemoteQueryResultResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostRemoteQueryResultRequest{
+		HostRemoteQueryResultPayload: result,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Ping sends a ping request to the orbit/ping endpoint.
 func (oc *OrbitClient) Ping() error {
 	verb, path := "HEAD", "/api/fleet/orbit/ping"
@@ -431,3 +555,33 @@ func (oc *OrbitClient) SetOrUpdateDiskEncryptionKey(diskEncryptionStatus fleet.O
 	}
 	return nil
 }
+
+// SendLinuxKeyEscrowResponse sends the LUKS passphrase escrowed by a Linux host,
+// or the error that prevented escrowing it, to the server.
+func (oc *OrbitClient) SendLinuxKeyEscrowResponse(payload fleet.OrbitHostLUKSDataPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/luks_data"
+
+	var resp orbitPostLUKSResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostLUKSRequest{
+		Passphrase:  payload.Passphrase,
+		KeySlot:     payload.KeySlot,
+		ClientError: payload.ClientError,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SendLinuxDiskEncryptionEnforcementStatus sends the state of the enforcement
+// of disk encryption on a Linux host whose root volume is not encrypted.
+func (oc *OrbitClient) SendLinuxDiskEncryptionEnforcementStatus(payload fleet.OrbitLinuxDiskEncryptionEnforcementPayload) error {
+	verb, path := "POST", "/api/fleet/orbit/disk_encryption_enforcement"
+
+	var resp orbitPostLinuxDiskEncryptionEnforcementResponse
+	if err := oc.authenticatedRequest(verb, path, &orbitPostLinuxDiskEncryptionEnforcementRequest{
+		OrbitLinuxDiskEncryptionEnforcementPayload: payload,
+	}, &resp); err != nil {
+		return err
+	}
+	return nil
+}
@@ -3,9 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/pkg/optjson"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/fleetdm/fleet/v4/server/ptr"
@@ -32,6 +37,18 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
 			return nil, nil
 		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
 		ctx = test.HostContext(ctx, &fleet.Host{
 			OsqueryHostID: ptr.String("test"),
 			ID:            1,
@@ -92,6 +109,18 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
 			return nil, nil
 		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
 
 		ctx = test.HostContext(ctx, &fleet.Host{
 			OsqueryHostID: ptr.String("test"),
@@ -151,6 +180,18 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
 			return nil, nil
 		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
 
 		team := fleet.Team{ID: 1}
 		teamMDM := fleet.TeamMDM{}
@@ -248,6 +289,18 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
 			return nil, nil
 		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
 
 		appCfg := &fleet.AppConfig{MDM: fleet.MDM{EnabledAndConfigured: true}}
 		appCfg.MDM.MacOSUpdates.Deadline = optjson.SetString("2022-04-01")
@@ -258,6 +311,18 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
 			return nil, nil
 		}
+		ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+			return nil, nil
+		}
+		ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+			return nil, nil
+		}
 		ds.GetHostOperatingSystemFunc = func(ctx context.Context, hostID uint) (*fleet.OperatingSystem, error) {
 			return os, nil
 		}
@@ -315,3 +380,453 @@ func TestGetOrbitConfigNudge(t *testing.T) {
 		require.True(t, ds.GetHostOperatingSystemFuncInvoked)
 	})
 }
+
+func TestSaveHostTamperEvent(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	var webhookPayloads []map[string]interface{}
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		webhookPayloads = append(webhookPayloads, payload)
+	}))
+	defer webhookSrv.Close()
+
+	appCfg := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appCfg, nil
+	}
+	var activities []fleet.ActivityTypeHostTamperDetected
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		act, ok := activity.(fleet.ActivityTypeHostTamperDetected)
+		require.True(t, ok)
+		activities = append(activities, act)
+		return nil
+	}
+
+	// no host in context
+	err := svc.SaveHostTamperEvent(ctx, &fleet.HostTamperEventPayload{Type: fleet.HostTamperEventServiceDisabled})
+	require.Error(t, err)
+
+	hostCtx := test.HostContext(ctx, &fleet.Host{ID: 1, Hostname: "host1", HardwareSerial: "ABC"})
+
+	// invalid payloads
+	err = svc.SaveHostTamperEvent(hostCtx, nil)
+	require.ErrorContains(t, err, "missing tamper event")
+	err = svc.SaveHostTamperEvent(hostCtx, &fleet.HostTamperEventPayload{Type: "no-such-type"})
+	require.ErrorContains(t, err, "invalid tamper event type")
+	require.Empty(t, activities)
+
+	// webhook disabled
+	detectedAt := time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC)
+	err = svc.SaveHostTamperEvent(hostCtx, &fleet.HostTamperEventPayload{
+		Type:       fleet.HostTamperEventConfigModified,
+		Details:    strings.Repeat("a", fleet.MaxHostTamperEventDetailsLength+10),
+		DetectedAt: detectedAt,
+	})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	require.Equal(t, uint(1), activities[0].HostID)
+	require.Equal(t, "host1", activities[0].HostDisplayName)
+	require.Equal(t, fleet.HostTamperEventConfigModified, activities[0].EventType)
+	require.Len(t, activities[0].Details, fleet.MaxHostTamperEventDetailsLength)
+	require.Equal(t, detectedAt, activities[0].DetectedAt)
+	require.Empty(t, webhookPayloads)
+
+	// webhook enabled
+	appCfg.WebhookSettings.TamperEventsWebhook = fleet.TamperEventsWebhookSettings{Enable: true, DestinationURL: webhookSrv.URL}
+	err = svc.SaveHostTamperEvent(hostCtx, &fleet.HostTamperEventPayload{Type: fleet.HostTamperEventOsquerydKilled, Details: "osqueryd exited 3 times"})
+	require.NoError(t, err)
+	require.Len(t, activities, 2)
+	require.False(t, activities[1].DetectedAt.IsZero())
+	require.Len(t, webhookPayloads, 1)
+	data := webhookPayloads[0]["data"].(map[string]interface{})
+	require.Equal(t, "osqueryd_killed", data["event_type"])
+	require.Equal(t, "ABC", data["host_serial"])
+	require.Equal(t, "osqueryd exited 3 times", data["details"])
+
+	// webhook failure does not fail the request
+	webhookSrv.Close()
+	err = svc.SaveHostTamperEvent(hostCtx, &fleet.HostTamperEventPayload{Type: fleet.HostTamperEventServiceDisabled})
+	require.NoError(t, err)
+	require.Len(t, activities, 3)
+}
+
+func TestGetOrbitConfigWindowsService(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	agentOpts := json.RawMessage(`{
+		"windows_service": {
+			"recovery_actions": [{"type": "restart", "delay_seconds": 60}],
+			"reset_period_seconds": 3600,
+			"watchdog_heartbeat_seconds": 300
+		}
+	}`)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: &agentOpts}, nil
+	}
+	ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+		return nil, nil
+	}
+
+	// only sent to windows hosts
+	cfg, err := svc.GetOrbitConfig(test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "darwin"}))
+	require.NoError(t, err)
+	require.Nil(t, cfg.WindowsService)
+
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, &fleet.Host{ID: 2, Platform: "windows"}))
+	require.NoError(t, err)
+	require.Equal(t, &fleet.OrbitWindowsServiceConfig{
+		RecoveryActions:          []fleet.OrbitServiceRecoveryAction{{Type: fleet.OrbitServiceRecoveryActionRestart, DelaySeconds: 60}},
+		ResetPeriodSeconds:       3600,
+		WatchdogHeartbeatSeconds: 300,
+	}, cfg.WindowsService)
+}
+
+func TestGetOrbitConfigFeatureFlags(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	var flags []fleet.OrbitFeatureFlag
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{OrbitFeatureFlags: flags}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+	ds.TeamMDMConfigFunc = func(ctx context.Context, teamID uint) (*fleet.TeamMDM, error) {
+		return &fleet.TeamMDM{}, nil
+	}
+	ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+		return nil, nil
+	}
+
+	noTeamHost := &fleet.Host{ID: 1, UUID: "uuid-1", Platform: "darwin"}
+	teamHost := &fleet.Host{ID: 2, UUID: "uuid-2", Platform: "windows", TeamID: ptr.Uint(1)}
+
+	// no flags defined
+	cfg, err := svc.GetOrbitConfig(test.HostContext(ctx, noTeamHost))
+	require.NoError(t, err)
+	require.Nil(t, cfg.FeatureFlags)
+
+	flags = []fleet.OrbitFeatureFlag{
+		{Name: "installer_queue", Enabled: true, TeamIDs: []uint{1}},
+		{Name: "new_tables", Enabled: true, TeamIDs: []uint{0}},
+		{Name: "disabled", Enabled: false},
+		{Name: "nobody", Enabled: true, Percentage: ptr.Int(0)},
+	}
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, noTeamHost))
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"installer_queue": false, "new_tables": true, "disabled": false, "nobody": false}, cfg.FeatureFlags)
+
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, teamHost))
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"installer_queue": true, "new_tables": false, "disabled": false, "nobody": false}, cfg.FeatureFlags)
+}
+
+func TestSaveHostServiceRecovery(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	var activities []fleet.ActivityTypeFleetdServiceRecovered
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		act, ok := activity.(fleet.ActivityTypeFleetdServiceRecovered)
+		require.True(t, ok)
+		activities = append(activities, act)
+		return nil
+	}
+
+	// no host in context
+	err := svc.SaveHostServiceRecovery(ctx, &fleet.HostServiceRecoveryPayload{Reason: fleet.HostServiceRecoveryCrash})
+	require.Error(t, err)
+
+	hostCtx := test.HostContext(ctx, &fleet.Host{ID: 1, Hostname: "host1"})
+
+	// invalid payloads
+	err = svc.SaveHostServiceRecovery(hostCtx, nil)
+	require.ErrorContains(t, err, "missing service recovery")
+	err = svc.SaveHostServiceRecovery(hostCtx, &fleet.HostServiceRecoveryPayload{Reason: "no-such-reason"})
+	require.ErrorContains(t, err, "invalid service recovery reason")
+	require.Empty(t, activities)
+
+	recoveredAt := time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC)
+	err = svc.SaveHostServiceRecovery(hostCtx, &fleet.HostServiceRecoveryPayload{
+		Reason:      fleet.HostServiceRecoveryWatchdog,
+		Details:     strings.Repeat("a", fleet.MaxHostServiceRecoveryDetailsLength+10),
+		RecoveredAt: recoveredAt,
+	})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	require.Equal(t, uint(1), activities[0].HostID)
+	require.Equal(t, "host1", activities[0].HostDisplayName)
+	require.Equal(t, fleet.HostServiceRecoveryWatchdog, activities[0].Reason)
+	require.Len(t, activities[0].Details, fleet.MaxHostServiceRecoveryDetailsLength)
+	require.Equal(t, recoveredAt, activities[0].RecoveredAt)
+
+	err = svc.SaveHostServiceRecovery(hostCtx, &fleet.HostServiceRecoveryPayload{Reason: fleet.HostServiceRecoveryCrash})
+	require.NoError(t, err)
+	require.Len(t, activities, 2)
+	require.False(t, activities[1].RecoveredAt.IsZero())
+}
+
+func TestGetOrbitConfigLinuxDiskEncryptionEscrow(t *testing.T) {
+	ds := new(mock.Store)
+
+	appCfg := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appCfg, nil
+	}
+	teamMDM := &fleet.TeamMDM{}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+	ds.TeamMDMConfigFunc = func(ctx context.Context, teamID uint) (*fleet.TeamMDM, error) {
+		return teamMDM, nil
+	}
+	ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+		return nil, nil
+	}
+
+	newHost := func(teamID *uint) *fleet.Host {
+		return &fleet.Host{
+			ID:                    1,
+			Platform:              "ubuntu",
+			OsqueryHostID:         ptr.String("osquery-1"),
+			DiskEncryptionEnabled: ptr.Bool(true),
+			TeamID:                teamID,
+		}
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	// disk encryption is not enforced
+	cfg, err := svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil)))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(ptr.Uint(1))))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+
+	appCfg.MDM.EnableDiskEncryption = optjson.SetBool(true)
+	teamMDM.EnableDiskEncryption = true
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil)))
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(ptr.Uint(1))))
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+
+	// the key was already escrowed
+	host := newHost(nil)
+	host.MDM.EncryptionKeyAvailable = true
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, host))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+
+	// a rotation was requested, the flag is kept until the new key is escrowed
+	host.DiskEncryptionResetRequested = ptr.Bool(true)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, host))
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+	require.False(t, cfg.Notifications.RotateDiskEncryptionKey)
+	require.False(t, ds.SetDiskEncryptionResetStatusFuncInvoked)
+
+	// the key cannot be escrowed without the server private key
+	fleetCfg := config.TestConfig()
+	fleetCfg.Server.PrivateKey = ""
+	svc, ctx = newTestServiceWithConfig(t, ds, fleetCfg, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil)))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+}
+
+func TestGetOrbitConfigLinuxDiskEncryptionEnforcement(t *testing.T) {
+	ds := new(mock.Store)
+
+	appCfg := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appCfg, nil
+	}
+	teamMDM := &fleet.TeamMDM{}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+	ds.TeamMDMConfigFunc = func(ctx context.Context, teamID uint) (*fleet.TeamMDM, error) {
+		return teamMDM, nil
+	}
+	ds.ListPendingHostScriptExecutionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostScriptResult, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostLogCollectionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostLogCollection, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostFileRetrievalsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostFileRetrieval, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostPprofCapturesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPprofCapture, error) {
+		return nil, nil
+	}
+	ds.ListPendingHostRemoteQueriesFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostRemoteQuery, error) {
+		return nil, nil
+	}
+
+	newHost := func(teamID *uint, encrypted *bool) *fleet.Host {
+		return &fleet.Host{
+			ID:                    1,
+			Platform:              "ubuntu",
+			OsqueryHostID:         ptr.String("osquery-1"),
+			DiskEncryptionEnabled: encrypted,
+			TeamID:                teamID,
+		}
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+
+	// disk encryption is not enforced
+	cfg, err := svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil, ptr.Bool(false))))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(ptr.Uint(1), ptr.Bool(false))))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+
+	appCfg.MDM.EnableDiskEncryption = optjson.SetBool(true)
+	teamMDM.EnableDiskEncryption = true
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil, ptr.Bool(false))))
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+	require.False(t, cfg.Notifications.RunLinuxDiskEncryptionEscrow)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(ptr.Uint(1), ptr.Bool(false))))
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+
+	// the disk is encrypted, or its encryption is not known yet
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil, ptr.Bool(true))))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+	cfg, err = svc.GetOrbitConfig(test.HostContext(ctx, newHost(nil, nil)))
+	require.NoError(t, err)
+	require.False(t, cfg.Notifications.EnforceLinuxDiskEncryption)
+}
+
+func TestSetLinuxDiskEncryptionEnforcementStatus(t *testing.T) {
+	ds := new(mock.Store)
+	var saved struct {
+		status fleet.LinuxDiskEncryptionEnforcementStatus
+		detail string
+	}
+	ds.SetHostLinuxDiskEncryptionEnforcementFunc = func(ctx context.Context, hostID uint, status fleet.LinuxDiskEncryptionEnforcementStatus, detail string) error {
+		saved.status, saved.detail = status, detail
+		return nil
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+	linuxCtx := test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "ubuntu"})
+
+	err := svc.SetLinuxDiskEncryptionEnforcementStatus(test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "windows"}),
+		fleet.OrbitLinuxDiskEncryptionEnforcementPayload{Status: fleet.LinuxDiskEncryptionEnforcementActionRequired})
+	require.ErrorContains(t, err, "not a Linux host")
+	err = svc.SetLinuxDiskEncryptionEnforcementStatus(linuxCtx, fleet.OrbitLinuxDiskEncryptionEnforcementPayload{Status: "verified"})
+	require.ErrorContains(t, err, "invalid status")
+	require.False(t, ds.SetHostLinuxDiskEncryptionEnforcementFuncInvoked)
+
+	err = svc.SetLinuxDiskEncryptionEnforcementStatus(linuxCtx, fleet.OrbitLinuxDiskEncryptionEnforcementPayload{Status: fleet.LinuxDiskEncryptionEnforcementActionRequired})
+	require.NoError(t, err)
+	require.Equal(t, fleet.LinuxDiskEncryptionEnforcementActionRequired, saved.status)
+	require.Empty(t, saved.detail)
+
+	// the detail is truncated
+	err = svc.SetLinuxDiskEncryptionEnforcementStatus(linuxCtx, fleet.OrbitLinuxDiskEncryptionEnforcementPayload{
+		Status: fleet.LinuxDiskEncryptionEnforcementFailed,
+		Detail: strings.Repeat("a", maxLinuxDiskEncryptionEnforcementDetail+10),
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.LinuxDiskEncryptionEnforcementFailed, saved.status)
+	require.Len(t, saved.detail, maxLinuxDiskEncryptionEnforcementDetail)
+}
+
+func TestEscrowLinuxDiskEncryptionKey(t *testing.T) {
+	ds := new(mock.Store)
+	var saved struct {
+		passphrase  string
+		keySlot     *uint
+		clientError string
+	}
+	ds.SaveLUKSDiskEncryptionKeyFunc = func(ctx context.Context, hostID uint, passphrase string, keySlot *uint, clientError string) error {
+		saved.passphrase, saved.keySlot, saved.clientError = passphrase, keySlot, clientError
+		return nil
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+	linuxCtx := test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "ubuntu"})
+
+	err := svc.EscrowLinuxDiskEncryptionKey(test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "darwin"}), "passphrase", ptr.Uint(1), "")
+	require.ErrorContains(t, err, "not a Linux host")
+
+	err = svc.EscrowLinuxDiskEncryptionKey(linuxCtx, "", ptr.Uint(1), "")
+	require.ErrorContains(t, err, "passphrase is required")
+	err = svc.EscrowLinuxDiskEncryptionKey(linuxCtx, "passphrase", nil, "")
+	require.ErrorContains(t, err, "key_slot must be")
+	err = svc.EscrowLinuxDiskEncryptionKey(linuxCtx, "passphrase", ptr.Uint(32), "")
+	require.ErrorContains(t, err, "key_slot must be")
+	require.False(t, ds.SaveLUKSDiskEncryptionKeyFuncInvoked)
+
+	err = svc.EscrowLinuxDiskEncryptionKey(linuxCtx, "passphrase", ptr.Uint(1), "")
+	require.NoError(t, err)
+	require.Equal(t, "passphrase", saved.passphrase)
+	require.Equal(t, ptr.Uint(1), saved.keySlot)
+
+	// a client error is recorded without a passphrase
+	err = svc.EscrowLinuxDiskEncryptionKey(linuxCtx, "", nil, strings.Repeat("a", 300))
+	require.NoError(t, err)
+	require.Len(t, saved.clientError, 255)
+
+	// the passphrase cannot be stored without the server private key
+	ds.SaveLUKSDiskEncryptionKeyFuncInvoked = false
+	cfg := config.TestConfig()
+	cfg.Server.PrivateKey = ""
+	svc, ctx = newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{SkipCreateTestUsers: true})
+	err = svc.EscrowLinuxDiskEncryptionKey(test.HostContext(ctx, &fleet.Host{ID: 1, Platform: "ubuntu"}), "passphrase", ptr.Uint(1), "")
+	require.ErrorContains(t, err, "server private key")
+	require.False(t, ds.SaveLUKSDiskEncryptionKeyFuncInvoked)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -131,9 +132,23 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 
 	logging.WithExtras(ctx, "hostIdentifier", hostIdentifier)
 
-	secret, err := svc.ds.VerifyEnrollSecret(ctx, enrollSecret)
+	// the device's uuid and serial from the system_info table provided with
+	// the osquery enrollment
+	var hardwareUUID, hardwareSerial string
+	if r, ok := hostDetails["system_info"]; ok {
+		hardwareUUID = r["uuid"]
+		hardwareSerial = r["hardware_serial"]
+	}
+
+	secret, enrollCert, err := svc.verifyEnrollCredentials(ctx, enrollSecret, hardwareUUID)
 	if err != nil {
-		return "", newOsqueryErrorWithInvalidNode("enroll failed: " + err.Error())
+		// report the reason of a rejected client certificate to osquery
+		msg := err.Error()
+		var internalErr fleet.ErrWithInternal
+		if errors.As(err, &internalErr) {
+			msg = internalErr.Internal()
+		}
+		return "", newOsqueryErrorWithInvalidNode("enroll failed: " + msg)
 	}
 
 	nodeKey, err := server.GenerateRandomText(svc.config.Osquery.NodeKeySize)
@@ -154,14 +169,6 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 		return "", newOsqueryErrorWithInvalidNode(fmt.Sprintf("enroll host failed: maximum number of hosts reached: %s", deviceCount))
 	}
 
-	// the the device's uuid and serial from the system_info table provided with
-	// the osquery enrollment
-	var hardwareUUID, hardwareSerial string
-	if r, ok := hostDetails["system_info"]; ok {
-		hardwareUUID = r["uuid"]
-		hardwareSerial = r["hardware_serial"]
-	}
-
 	appConfig, err := svc.ds.AppConfig(ctx)
 	if err != nil {
 		return "", newOsqueryErrorWithInvalidNode("app config load failed: " + err.Error())
@@ -171,6 +178,10 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 	if err != nil {
 		return "", newOsqueryErrorWithInvalidNode("save enroll failed: " + err.Error())
 	}
+	if err := svc.recordEnrollCertificate(ctx, host.ID, enrollCert); err != nil {
+		return "", newOsqueryErrorWithInvalidNode("save enroll certificate failed: " + err.Error())
+	}
+	svc.applyEnrollSecretHostRules(ctx, host.ID, secret, appConfig)
 
 	features, err := svc.HostFeatures(ctx, host)
 	if err != nil {
@@ -215,6 +226,54 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 	return nodeKey, nil
 }
 
+// applyEnrollSecretHostRules adds the host to the labels of the enroll secret
+// it enrolled with and sets the custom fields of the secret that the host
+// doesn't have a value for. The labels or custom fields that were deleted or
+// became invalid since the secret was saved are ignored. Errors are logged and
+// don't fail the enrollment.
+func (svc *Service) applyEnrollSecretHostRules(ctx context.Context, hostID uint, secret *fleet.EnrollSecret, appConfig *fleet.AppConfig) {
+	if len(secret.Labels) > 0 {
+		labelIDs, err := svc.ds.LabelIDsByName(ctx, secret.Labels)
+		if err != nil {
+			logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "get enroll secret labels"))
+		} else {
+			ids := make([]uint, 0, len(labelIDs))
+			for _, id := range labelIDs {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			if err := svc.ds.AddLabelsToHost(ctx, hostID, ids); err != nil {
+				logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "add host to enroll secret labels"))
+			}
+		}
+	}
+
+	if len(secret.CustomFields) > 0 {
+		current, err := svc.ds.GetHostCustomFields(ctx, hostID)
+		if err != nil {
+			logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "get host custom fields"))
+			return
+		}
+		payload := fleet.HostCustomFieldsPayload{CustomFields: make(map[string]*string)}
+		for name, value := range secret.CustomFields {
+			if current.CustomFields[name] != "" {
+				continue
+			}
+			def, ok := fleet.HostCustomFieldDefinitionByName(appConfig.HostCustomFields, name)
+			if !ok || def.ValidateValue(value) != nil {
+				continue
+			}
+			payload.CustomFields[name] = ptr.String(value)
+		}
+		if len(payload.CustomFields) == 0 {
+			return
+		}
+		if err := svc.ds.UpdateHostCustomFields(ctx, hostID, payload); err != nil {
+			logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "set enroll secret custom fields"))
+		}
+	}
+}
+
 var counter = int64(0)
 
 func (svc *Service) serialUpdateHost(host *fleet.Host) {
@@ -406,11 +465,19 @@ func (svc *Service) GetClientConfig(ctx context.Context) (map[string]interface{}
 		}
 	}
 
+	withheld, err := svc.osqueryConfigWithheld(ctx, host)
+	if err != nil {
+		return nil, newOsqueryError("internal error: " + err.Error())
+	}
+
 	packConfig := fleet.Packs{}
 
-	packs, err := svc.ds.ListPacksForHost(ctx, host.ID)
-	if err != nil {
-		return nil, newOsqueryError("database error: " + err.Error())
+	var packs []*fleet.Pack
+	if !withheld {
+		packs, err = svc.ds.ListPacksForHost(ctx, host.ID)
+		if err != nil {
+			return nil, newOsqueryError("database error: " + err.Error())
+		}
 	}
 	for _, pack := range packs {
 		// first, we must figure out what queries are in this pack
@@ -452,25 +519,27 @@ func (svc *Service) GetClientConfig(ctx context.Context) (map[string]interface{}
 		}
 	}
 
-	globalQueries, err := svc.getScheduledQueries(ctx, nil)
-	if err != nil {
-		return nil, newOsqueryError("database error: " + err.Error())
-	}
-	if len(globalQueries) > 0 {
-		packConfig["Global"] = fleet.PackContent{
-			Queries: globalQueries,
-		}
-	}
-
-	if host.TeamID != nil {
-		teamQueries, err := svc.getScheduledQueries(ctx, host.TeamID)
+	if !withheld {
+		globalQueries, err := svc.getScheduledQueries(ctx, nil)
 		if err != nil {
 			return nil, newOsqueryError("database error: " + err.Error())
 		}
-		if len(teamQueries) > 0 {
-			packName := fmt.Sprintf("team-%d", *host.TeamID)
-			packConfig[packName] = fleet.PackContent{
-				Queries: teamQueries,
+		if len(globalQueries) > 0 {
+			packConfig["Global"] = fleet.PackContent{
+				Queries: globalQueries,
+			}
+		}
+
+		if host.TeamID != nil {
+			teamQueries, err := svc.getScheduledQueries(ctx, host.TeamID)
+			if err != nil {
+				return nil, newOsqueryError("database error: " + err.Error())
+			}
+			if len(teamQueries) > 0 {
+				packName := fmt.Sprintf("team-%d", *host.TeamID)
+				packConfig[packName] = fleet.PackContent{
+					Queries: teamQueries,
+				}
 			}
 		}
 	}
@@ -788,10 +857,21 @@ func (svc *Service) labelQueriesForHost(ctx context.Context, host *fleet.Host) (
 // It returns (nil, true, nil) if the interval is so that policies should be executed on the host, but there are no policies
 // assigned to such host.
 func (svc *Service) policyQueriesForHost(ctx context.Context, host *fleet.Host) (policyQueries map[string]string, noPoliciesForHost bool, err error) {
+	if host.PendingApproval {
+		// policies are not run on hosts that are pending enrollment approval.
+		return nil, false, nil
+	}
 	policyReportedAt := svc.task.GetHostPolicyReportedAt(ctx, host)
 	if !svc.shouldUpdate(policyReportedAt, svc.config.Osquery.PolicyUpdateInterval, host.ID) && !host.RefetchRequested {
 		return nil, false, nil
 	}
+	withheld, err := svc.osqueryConfigWithheld(ctx, host)
+	if err != nil {
+		return nil, false, err
+	}
+	if withheld {
+		return nil, false, nil
+	}
 	policyQueries, err = svc.ds.PolicyQueriesForHost(ctx, host)
 	if err != nil {
 		return nil, false, ctxerr.Wrap(ctx, err, "retrieve policy queries")
@@ -802,6 +882,31 @@ func (svc *Service) policyQueriesForHost(ctx context.Context, host *fleet.Host)
 	return policyQueries, false, nil
 }
 
+// osqueryConfigWithheld returns true if the scheduled queries and policies
+// are withheld from the host because the features of its team require the
+// macOS and Windows hosts to be enrolled in Fleet MDM and the host is not.
+func (svc *Service) osqueryConfigWithheld(ctx context.Context, host *fleet.Host) (bool, error) {
+	switch host.FleetPlatform() {
+	case "darwin", "windows":
+	default:
+		return false, nil
+	}
+
+	features, err := svc.HostFeatures(ctx, host)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "get host features")
+	}
+	if !features.RequireMDMEnrollment {
+		return false, nil
+	}
+
+	hostMDM, err := svc.ds.GetHostMDM(ctx, host.ID)
+	if err != nil && !fleet.IsNotFound(err) {
+		return false, ctxerr.Wrap(ctx, err, "get host MDM information")
+	}
+	return !hostMDM.IsFleetEnrolled(), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Write Distributed Query Results
 ////////////////////////////////////////////////////////////////////////////////
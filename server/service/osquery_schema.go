@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/schema"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Osquery Schema Tables
+////////////////////////////////////////////////////////////////////////////////
+
+type listOsquerySchemaTablesRequest struct {
+	fleet.OsquerySchemaOptions
+}
+
+type listOsquerySchemaTablesResponse struct {
+	Tables []schema.Table `json:"tables"`
+	Err    error          `json:"error,omitempty"`
+}
+
+func (r listOsquerySchemaTablesResponse) error() error { return r.Err }
+
+func listOsquerySchemaTablesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listOsquerySchemaTablesRequest)
+	tables, err := svc.ListOsquerySchemaTables(ctx, req.OsquerySchemaOptions)
+	if err != nil {
+		return listOsquerySchemaTablesResponse{Err: err}, nil
+	}
+	return listOsquerySchemaTablesResponse{Tables: tables}, nil
+}
+
+func (svc *Service) ListOsquerySchemaTables(ctx context.Context, opts fleet.OsquerySchemaOptions) ([]schema.Table, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.OsquerySchema{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if err := opts.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("platform", err.Error()))
+	}
+
+	catalog, err := schema.Catalog()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "load osquery schema catalog")
+	}
+	if opts.Platform == "" {
+		return catalog, nil
+	}
+
+	tables := make([]schema.Table, 0, len(catalog))
+	for _, t := range catalog {
+		if t.SupportsPlatform(opts.Platform) {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOsquerySchemaTables(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	// requires a logged in user
+	_, err := svc.ListOsquerySchemaTables(ctx, fleet.OsquerySchemaOptions{})
+	checkAuthErr(t, true, err)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: test.UserTeamGitOpsTeam1})
+
+	all, err := svc.ListOsquerySchemaTables(ctx, fleet.OsquerySchemaOptions{})
+	require.NoError(t, err)
+	names := make(map[string]bool, len(all))
+	for _, tbl := range all {
+		names[tbl.Name] = true
+	}
+	// osquery and fleetd tables
+	require.True(t, names["users"])
+	require.True(t, names["smart_disk_health"])
+
+	windows, err := svc.ListOsquerySchemaTables(ctx, fleet.OsquerySchemaOptions{Platform: "windows"})
+	require.NoError(t, err)
+	require.Less(t, len(windows), len(all))
+	for _, tbl := range windows {
+		require.True(t, tbl.SupportsPlatform("windows"), tbl.Name)
+	}
+
+	for _, platform := range []string{"foobar", "darwin,windows"} {
+		_, err = svc.ListOsquerySchemaTables(ctx, fleet.OsquerySchemaOptions{Platform: platform})
+		var invalidArg *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &invalidArg, platform)
+	}
+}
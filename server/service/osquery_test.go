@@ -302,6 +302,66 @@ func TestEnrollAgent(t *testing.T) {
 	assert.NotEmpty(t, nodeKey)
 }
 
+func TestEnrollAgentEnrollSecretHostRules(t *testing.T) {
+	ds := new(mock.Store)
+	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
+		return &fleet.EnrollSecret{
+			Secret:       secret,
+			Labels:       []string{"kiosk", "deleted"},
+			CustomFields: fleet.HostCustomFieldValues{"role": "kiosk", "owner": "it", "removed": "x"},
+		}, nil
+	}
+	ds.EnrollHostFunc = func(ctx context.Context, isMDMEnabled bool, osqueryHostId, hUUID, hSerial, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+		return &fleet.Host{ID: 42, OsqueryHostID: &osqueryHostId, NodeKey: &nodeKey}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostCustomFields: []fleet.HostCustomFieldDefinition{
+			{Name: "role", Type: fleet.HostCustomFieldTypeString},
+			{Name: "owner", Type: fleet.HostCustomFieldTypeString},
+		}}, nil
+	}
+	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string) (map[string]uint, error) {
+		require.ElementsMatch(t, []string{"kiosk", "deleted"}, names)
+		return map[string]uint{"kiosk": 7}, nil
+	}
+	ds.AddLabelsToHostFunc = func(ctx context.Context, hostID uint, labelIDs []uint) error {
+		require.Equal(t, uint(42), hostID)
+		require.Equal(t, []uint{7}, labelIDs)
+		return nil
+	}
+	ds.GetHostCustomFieldsFunc = func(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
+		return &fleet.HostCustomFields{HostID: hostID, CustomFields: fleet.HostCustomFieldValues{"owner": "alice"}}, nil
+	}
+	ds.UpdateHostCustomFieldsFunc = func(ctx context.Context, hostID uint, payload fleet.HostCustomFieldsPayload) error {
+		require.Equal(t, uint(42), hostID)
+		require.Nil(t, payload.Notes)
+		// owner already has a value and removed is not defined anymore
+		require.Equal(t, map[string]*string{"role": ptr.String("kiosk")}, payload.CustomFields)
+		return nil
+	}
+
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	nodeKey, err := svc.EnrollAgent(ctx, "kiosk_secret", "host123", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nodeKey)
+	require.True(t, ds.AddLabelsToHostFuncInvoked)
+	require.True(t, ds.UpdateHostCustomFieldsFuncInvoked)
+
+	// errors applying the rules don't fail the enrollment
+	ds.AddLabelsToHostFunc = func(ctx context.Context, hostID uint, labelIDs []uint) error {
+		return errors.New("boom")
+	}
+	ds.GetHostCustomFieldsFunc = func(ctx context.Context, hostID uint) (*fleet.HostCustomFields, error) {
+		return nil, errors.New("boom")
+	}
+	ds.UpdateHostCustomFieldsFuncInvoked = false
+	nodeKey, err = svc.EnrollAgent(ctx, "kiosk_secret", "host123", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nodeKey)
+	require.False(t, ds.UpdateHostCustomFieldsFuncInvoked)
+}
+
 func TestEnrollAgentEnforceLimit(t *testing.T) {
 	runTest := func(t *testing.T, pool fleet.RedisPool) {
 		const maxHosts = 2
@@ -1016,6 +1076,7 @@ func verifyDiscovery(t *testing.T, queries, discovery map[string]string) {
 		hostDetailQueryPrefix + "kubequery_info":             {},
 		hostDetailQueryPrefix + "orbit_info":                 {},
 		hostDetailQueryPrefix + "software_vscode_extensions": {},
+		hostDetailQueryPrefix + "cloud_instance_metadata":    {},
 	}
 	for name := range queries {
 		require.NotEmpty(t, discovery[name])
@@ -1696,11 +1757,12 @@ func TestDetailQueries(t *testing.T) {
 		return nil
 	}
 	ds.SetOrUpdateHostOrbitInfoFunc = func(
-		ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool,
+		ctx context.Context, hostID uint, version string, desktopVersion sql.NullString, scriptsEnabled sql.NullBool, timezone sql.NullString,
 	) error {
 		require.Equal(t, "42", version)
 		require.Equal(t, sql.NullString{String: "1.2.3", Valid: true}, desktopVersion)
 		require.Equal(t, sql.NullBool{Bool: true, Valid: true}, scriptsEnabled)
+		require.Equal(t, sql.NullString{String: "Europe/Paris", Valid: true}, timezone)
 		return nil
 	}
 	ds.SetOrUpdateDeviceAuthTokenFunc = func(ctx context.Context, hostID uint, authToken string) error {
@@ -1875,7 +1937,8 @@ func TestDetailQueries(t *testing.T) {
 	{
 		"version": "42",
 		"desktop_version": "1.2.3",
-		"scripts_enabled": "1"
+		"scripts_enabled": "1",
+		"timezone": "Europe/Paris"
 	}
 ]
 }
@@ -2122,7 +2185,7 @@ func TestNewDistributedQueryCampaign(t *testing.T) {
 		},
 	})
 	q := "select year, month, day, hour, minutes, seconds from time"
-	campaign, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	campaign, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, nil)
 	require.NoError(t, err)
 	assert.Equal(t, gotQuery.ID, gotCampaign.QueryID)
 	assert.Equal(t, []*fleet.DistributedQueryCampaignTarget{
@@ -2138,6 +2201,21 @@ func TestNewDistributedQueryCampaign(t *testing.T) {
 		},
 	}, gotTargets,
 	)
+	assert.Nil(t, gotCampaign.PostProcessing)
+
+	// with post-processing
+	pp := &fleet.QueryPostProcessing{Function: fleet.QueryPostProcessingGroupBy, Column: "year"}
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}}, pp)
+	require.NoError(t, err)
+	assert.Equal(t, pp, gotCampaign.PostProcessing)
+
+	// invalid post-processing
+	gotCampaign = nil
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}}, &fleet.QueryPostProcessing{Function: fleet.QueryPostProcessingDistinct})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	require.ErrorContains(t, err, "column is required by the distinct function")
+	assert.Nil(t, gotCampaign)
 }
 
 func TestDistributedQueryResults(t *testing.T) {
@@ -2913,10 +2991,10 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 		return nil
 	}
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, nil)
 	require.Error(t, err)
 
-	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, nil)
 	require.Error(t, err)
 
 	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
@@ -2948,7 +3026,7 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 		return nil
 	}
 	lq.On("RunQuery", "21", "select 1;", []uint{1, 3, 5}).Return(nil)
-	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, nil)
 	require.NoError(t, err)
 }
 
@@ -3006,10 +3084,89 @@ func TestTeamMaintainerCanRunNewDistributedCampaigns(t *testing.T) {
 		return nil
 	}
 	lq.On("RunQuery", "0", "select year, month, day, hour, minutes, seconds from time", []uint{1, 3, 5}).Return(nil)
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}, TeamIDs: []uint{123}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}, TeamIDs: []uint{123}}, nil)
 	require.NoError(t, err)
 }
 
+func TestOsqueryConfigRequireMDMEnrollment(t *testing.T) {
+	ds := new(mock.Store)
+	lq := live_query_mock.New(t)
+	svc, ctx := newTestServiceWithClock(t, ds, nil, lq, clock.NewMockClock())
+
+	var requireMDM bool
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"baz":"bar"}}}`)),
+			Features:     fleet.Features{EnableHostUsers: true, RequireMDMEnrollment: requireMDM},
+		}, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{{ID: 1, Name: "pack"}}, nil
+	}
+	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, pid uint) (fleet.ScheduledQueryList, error) {
+		return []*fleet.ScheduledQuery{{Name: "time", Query: "select * from time", Interval: 30}}, nil
+	}
+	ds.ListScheduledQueriesForAgentsFunc = func(ctx context.Context, teamID *uint, queryReportsDisabled bool) ([]*fleet.Query, error) {
+		return []*fleet.Query{{Query: "SELECT 1", Name: "global", Interval: 10}}, nil
+	}
+	ds.LabelQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+		return map[string]string{}, nil
+	}
+	ds.PolicyQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+		return map[string]string{"1": "select 1"}, nil
+	}
+	var hostMDM *fleet.HostMDM
+	ds.GetHostMDMFunc = func(ctx context.Context, hostID uint) (*fleet.HostMDM, error) {
+		if hostMDM == nil {
+			return nil, newNotFoundError()
+		}
+		return hostMDM, nil
+	}
+	lq.On("QueriesForHost", uint(1)).Return(map[string]string{}, nil)
+
+	check := func(platform string, withheld bool) {
+		host := &fleet.Host{ID: 1, Platform: platform, Hostname: "host", RefetchRequested: true}
+		hctx := hostctx.NewContext(ctx, host)
+
+		conf, err := svc.GetClientConfig(hctx)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"baz": "bar"}, conf["options"])
+		if withheld {
+			require.NotContains(t, conf, "packs")
+		} else {
+			require.Contains(t, conf, "packs")
+		}
+
+		queries, _, _, err := svc.GetDistributedQueries(hctx)
+		require.NoError(t, err)
+		// the vitals of the host are still collected
+		require.Contains(t, queries, hostDetailQueryPrefix+"osquery_info")
+		if withheld {
+			require.NotContains(t, queries, hostPolicyQueryPrefix+"1")
+		} else {
+			require.Contains(t, queries, hostPolicyQueryPrefix+"1")
+		}
+	}
+
+	// the setting is disabled
+	check("darwin", false)
+	require.False(t, ds.GetHostMDMFuncInvoked)
+
+	requireMDM = true
+	check("darwin", true)
+	check("windows", true)
+	// only macOS and Windows hosts are affected
+	check("ubuntu", false)
+
+	// enrolled in a third-party MDM
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: "Intune"}
+	check("windows", true)
+
+	hostMDM = &fleet.HostMDM{Enrolled: true, Name: fleet.WellKnownMDMFleet}
+	check("darwin", false)
+	check("windows", false)
+}
+
 func TestPolicyQueries(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
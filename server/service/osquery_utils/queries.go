@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
@@ -373,7 +374,7 @@ FROM logical_drives WHERE file_system = 'NTFS' LIMIT 1;`,
 	"kubequery_info": {
 		Query:      `SELECT * from kubernetes_info`,
 		IngestFunc: ingestKubequeryInfo,
-		Discovery:  discoveryTable("kubernetes_info"),
+		Discovery:  DiscoveryTable("kubernetes_info"),
 	},
 }
 
@@ -460,7 +461,7 @@ var extraDetailQueries = map[string]DetailQuery{
 		Query:            `select enrolled, server_url, installed_from_dep, payload_identifier from mdm;`,
 		DirectIngestFunc: directIngestMDMMac,
 		Platforms:        []string{"darwin"},
-		Discovery:        discoveryTable("mdm"),
+		Discovery:        DiscoveryTable("mdm"),
 	},
 	"mdm_windows": {
 		// we get most of the MDM information for Windows from the
@@ -530,7 +531,7 @@ var extraDetailQueries = map[string]DetailQuery{
 		Query:            `select version, errors, warnings from munki_info;`,
 		DirectIngestFunc: directIngestMunkiInfo,
 		Platforms:        []string{"darwin"},
-		Discovery:        discoveryTable("munki_info"),
+		Discovery:        DiscoveryTable("munki_info"),
 	},
 	// On ChromeOS, the `users` table returns only the user signed into the primary chrome profile.
 	"chromeos_profile_user_info": {
@@ -541,7 +542,7 @@ var extraDetailQueries = map[string]DetailQuery{
 	"google_chrome_profiles": {
 		Query:            `SELECT email FROM google_chrome_profiles WHERE NOT ephemeral AND email <> ''`,
 		DirectIngestFunc: directIngestChromeProfiles,
-		Discovery:        discoveryTable("google_chrome_profiles"),
+		Discovery:        DiscoveryTable("google_chrome_profiles"),
 	},
 	"battery": {
 		Query:            `SELECT serial_number, cycle_count, health FROM battery;`,
@@ -632,7 +633,7 @@ var extraDetailQueries = map[string]DetailQuery{
 		Query:            `SELECT * FROM orbit_info`,
 		DirectIngestFunc: directIngestOrbitInfo,
 		Platforms:        append(fleet.HostLinuxOSs, "darwin", "windows"),
-		Discovery:        discoveryTable("orbit_info"),
+		Discovery:        DiscoveryTable("orbit_info"),
 	},
 	"disk_encryption_darwin": {
 		Query:            usesMacOSDiskEncryptionQuery,
@@ -672,6 +673,29 @@ var extraDetailQueries = map[string]DetailQuery{
 		Platforms:        []string{"windows"},
 		DirectIngestFunc: directIngestDiskEncryption,
 	},
+	"certificates_darwin": {
+		// The root certificates shipped with macOS are excluded, they are the
+		// same on all hosts and are managed by Apple.
+		Query:            `SELECT sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after, path FROM certificates WHERE path NOT LIKE '/System/%'`,
+		Platforms:        []string{"darwin"},
+		DirectIngestFunc: directIngestCertificates,
+		// the "certificates" table doesn't need a Discovery query as it is an
+		// official osquery table on darwin and windows, it is always present.
+	},
+	"certificates_windows": {
+		// Only the machine-wide certificate stores are collected, the stores of
+		// each user would report the same certificates many times.
+		Query:            `SELECT sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after, path FROM certificates WHERE store_location = 'LocalMachine'`,
+		Platforms:        []string{"windows"},
+		DirectIngestFunc: directIngestCertificates,
+	},
+	"cloud_instance_metadata": {
+		// The table returns no row if the host is not a cloud instance.
+		Query:            `SELECT provider, instance_id, region, account_id, tags FROM cloud_instance_metadata`,
+		Platforms:        append(fleet.HostLinuxOSs, "darwin", "windows"),
+		Discovery:        DiscoveryTable("cloud_instance_metadata"),
+		DirectIngestFunc: directIngestCloudInstanceMetadata,
+	},
 }
 
 // mdmQueries are used by the Fleet server to compliment certain MDM
@@ -683,13 +707,13 @@ var mdmQueries = map[string]DetailQuery{
 		Query:            `SELECT display_name, identifier, install_date FROM macos_profiles where type = "Configuration";`,
 		Platforms:        []string{"darwin"},
 		DirectIngestFunc: directIngestMacOSProfiles,
-		Discovery:        discoveryTable("macos_profiles"),
+		Discovery:        DiscoveryTable("macos_profiles"),
 	},
 	"mdm_config_profiles_windows": {
 		QueryFunc:        buildConfigProfilesWindowsQuery,
 		Platforms:        []string{"windows"},
 		DirectIngestFunc: directIngestWindowsProfiles,
-		Discovery:        discoveryTable("mdm_bridge"),
+		Discovery:        DiscoveryTable("mdm_bridge"),
 	},
 	// There are two mutually-exclusive queries used to read the FileVaultPRK depending on which
 	// extension tables are discovered on the agent. The preferred query uses the newer custom
@@ -724,7 +748,7 @@ var mdmQueries = map[string]DetailQuery{
 	SELECT encrypted, hex(line) as hex_line FROM de LEFT JOIN fl;`, usesMacOSDiskEncryptionQuery),
 		Platforms:        []string{"darwin"},
 		DirectIngestFunc: directIngestDiskEncryptionKeyFileLinesDarwin,
-		Discovery:        fmt.Sprintf(`SELECT 1 WHERE EXISTS (%s) AND NOT EXISTS (%s);`, strings.Trim(discoveryTable("file_lines"), ";"), strings.Trim(discoveryTable("filevault_prk"), ";")),
+		Discovery:        fmt.Sprintf(`SELECT 1 WHERE EXISTS (%s) AND NOT EXISTS (%s);`, strings.Trim(DiscoveryTable("file_lines"), ";"), strings.Trim(DiscoveryTable("filevault_prk"), ";")),
 	},
 	"mdm_disk_encryption_key_file_darwin": {
 		Query: fmt.Sprintf(`
@@ -734,7 +758,7 @@ var mdmQueries = map[string]DetailQuery{
 	SELECT encrypted, filevault_key FROM de LEFT JOIN fv;`, usesMacOSDiskEncryptionQuery),
 		Platforms:        []string{"darwin"},
 		DirectIngestFunc: directIngestDiskEncryptionKeyFileDarwin,
-		Discovery:        discoveryTable("filevault_prk"),
+		Discovery:        DiscoveryTable("filevault_prk"),
 	},
 	"mdm_device_id_windows": {
 		Query:            `SELECT name, data FROM registry WHERE path = 'HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Provisioning\OMADM\MDMDeviceID\DeviceClientId';`,
@@ -743,8 +767,8 @@ var mdmQueries = map[string]DetailQuery{
 	},
 }
 
-// discoveryTable returns a query to determine whether a table exists or not.
-func discoveryTable(tableName string) string {
+// DiscoveryTable returns a query to determine whether a table exists or not.
+func DiscoveryTable(tableName string) string {
 	return fmt.Sprintf("SELECT 1 FROM osquery_registry WHERE active = true AND registry = 'table' AND name = '%s';", tableName)
 }
 
@@ -760,7 +784,7 @@ func withCachedUsers(query string) string {
 var windowsUpdateHistory = DetailQuery{
 	Query:            `SELECT date, title FROM windows_update_history WHERE result_code = 'Succeeded'`,
 	Platforms:        []string{"windows"},
-	Discovery:        discoveryTable("windows_update_history"),
+	Discovery:        DiscoveryTable("windows_update_history"),
 	DirectIngestFunc: directIngestWindowsUpdateHistory,
 }
 
@@ -872,7 +896,7 @@ SELECT
   path AS installed_path
 FROM cached_users CROSS JOIN vscode_extensions USING (uid)`),
 	Platforms: append(fleet.HostLinuxOSs, "darwin", "windows"),
-	Discovery: discoveryTable("vscode_extensions"),
+	Discovery: DiscoveryTable("vscode_extensions"),
 	// Has no IngestFunc, DirectIngestFunc or DirectTaskIngestFunc because
 	// the results of this query are appended to the results of the other software queries.
 }
@@ -1100,7 +1124,12 @@ func directIngestOrbitInfo(ctx context.Context, logger log.Logger, host *fleet.H
 		scriptsEnabled.Bool = scriptsEnabledStr == "1"
 		scriptsEnabled.Valid = true
 	}
-	if err := ds.SetOrUpdateHostOrbitInfo(ctx, host.ID, version, desktopVersion, scriptsEnabled); err != nil {
+	// timezone is not reported by older versions of fleetd, and it is empty if
+	// fleetd could not determine the host's timezone.
+	var timezone sql.NullString
+	timezone.String = rows[0]["timezone"]
+	timezone.Valid = timezone.String != ""
+	if err := ds.SetOrUpdateHostOrbitInfo(ctx, host.ID, version, desktopVersion, scriptsEnabled, timezone); err != nil {
 		return ctxerr.Wrap(ctx, err, "directIngestOrbitInfo update host orbit info")
 	}
 
@@ -1219,6 +1248,83 @@ func directIngestBattery(ctx context.Context, logger log.Logger, host *fleet.Hos
 	return ds.ReplaceHostBatteries(ctx, host.ID, mapping)
 }
 
+func directIngestCertificates(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string) error {
+	certs := make([]*fleet.HostCertificate, 0, len(rows))
+	for _, row := range rows {
+		sha1 := strings.ToLower(row["sha1"])
+		if _, err := hex.DecodeString(sha1); err != nil || len(sha1) != 40 {
+			level.Debug(logger).Log("op", "directIngestCertificates", "skipped", "invalid sha1", "sha1", row["sha1"])
+			continue
+		}
+		notValidBefore, err := parseUnixTimestamp(row["not_valid_before"])
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestCertificates", "skipped", err, "sha1", sha1)
+			continue
+		}
+		notValidAfter, err := parseUnixTimestamp(row["not_valid_after"])
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestCertificates", "skipped", err, "sha1", sha1)
+			continue
+		}
+		certs = append(certs, &fleet.HostCertificate{
+			HostID:         host.ID,
+			SHA1:           sha1,
+			CommonName:     fmt.Sprintf("%.255s", row["common_name"]),
+			Subject:        fmt.Sprintf("%.1024s", row["subject"]),
+			Issuer:         fmt.Sprintf("%.1024s", row["issuer"]),
+			CA:             row["ca"] == "1",
+			SelfSigned:     row["self_signed"] == "1",
+			NotValidBefore: notValidBefore,
+			NotValidAfter:  notValidAfter,
+			Source:         fmt.Sprintf("%.255s", row["path"]),
+		})
+	}
+	return ds.ReplaceHostCertificates(ctx, host.ID, fleet.HostCertificateOriginOsquery, certs)
+}
+
+func directIngestCloudInstanceMetadata(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string) error {
+	if len(rows) == 0 {
+		// the host is not a cloud instance (anymore)
+		return ds.SetOrUpdateHostCloudInstance(ctx, host.ID, nil)
+	}
+	if len(rows) > 1 {
+		return ctxerr.Errorf(ctx, "directIngestCloudInstanceMetadata invalid number of rows: %d", len(rows))
+	}
+
+	row := rows[0]
+	provider := fleet.CloudProvider(row["provider"])
+	if !provider.IsValid() || row["instance_id"] == "" {
+		level.Debug(logger).Log("op", "directIngestCloudInstanceMetadata", "skipped", "invalid provider or instance ID", "provider", row["provider"])
+		return nil
+	}
+	var tags fleet.CloudInstanceTags
+	if row["tags"] != "" {
+		if err := json.Unmarshal([]byte(row["tags"]), &tags); err != nil {
+			// keep the rest of the metadata, the tags are informational
+			level.Debug(logger).Log("op", "directIngestCloudInstanceMetadata", "err", err)
+			tags = nil
+		}
+	}
+	return ds.SetOrUpdateHostCloudInstance(ctx, host.ID, &fleet.HostCloudInstance{
+		HostID:     host.ID,
+		Provider:   provider,
+		InstanceID: fmt.Sprintf("%.255s", row["instance_id"]),
+		Region:     fmt.Sprintf("%.255s", row["region"]),
+		AccountID:  fmt.Sprintf("%.255s", row["account_id"]),
+		Tags:       tags,
+	})
+}
+
+// parseUnixTimestamp parses a unix timestamp in seconds as reported by
+// osquery, which may have a fractional part.
+func parseUnixTimestamp(s string) (time.Time, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse unix timestamp %q: %w", s, err)
+	}
+	return time.Unix(int64(f), 0).UTC(), nil
+}
+
 func directIngestWindowsUpdateHistory(
 	ctx context.Context,
 	logger log.Logger,
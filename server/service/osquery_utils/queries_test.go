@@ -276,13 +276,16 @@ func TestGetDetailQueries(t *testing.T) {
 		"disk_encryption_linux",
 		"disk_encryption_windows",
 		"chromeos_profile_user_info",
+		"certificates_darwin",
+		"certificates_windows",
+		"cloud_instance_metadata",
 	}
 
 	require.Len(t, queriesNoConfig, len(baseQueries))
 	sortedKeysCompare(t, queriesNoConfig, baseQueries)
 
 	queriesWithoutWinOSVuln := GetDetailQueries(context.Background(), config.FleetConfig{Vulnerabilities: config.VulnerabilitiesConfig{DisableWinOSVulnerabilities: true}}, nil, nil)
-	require.Len(t, queriesWithoutWinOSVuln, 25)
+	require.Len(t, queriesWithoutWinOSVuln, 28)
 
 	queriesWithUsers := GetDetailQueries(context.Background(), config.FleetConfig{App: config.AppConfig{EnableScheduledQueryStats: true}}, nil, &fleet.Features{EnableHostUsers: true})
 	qs := append(baseQueries, "users", "users_chrome", "scheduled_query_stats")
@@ -955,6 +958,113 @@ func TestDirectIngestBattery(t *testing.T) {
 	require.True(t, ds.ReplaceHostBatteriesFuncInvoked)
 }
 
+func TestDirectIngestCertificates(t *testing.T) {
+	ds := new(mock.Store)
+	ds.ReplaceHostCertificatesFunc = func(ctx context.Context, hostID uint, origin fleet.HostCertificateOrigin, certs []*fleet.HostCertificate) error {
+		require.Equal(t, fleet.HostCertificateOriginOsquery, origin)
+		require.Equal(t, []*fleet.HostCertificate{
+			{
+				HostID:         1,
+				SHA1:           "0123456789abcdef0123456789abcdef01234567",
+				CommonName:     "Example Root CA",
+				Subject:        "/CN=Example Root CA",
+				Issuer:         "/CN=Example Root CA",
+				CA:             true,
+				SelfSigned:     true,
+				NotValidBefore: time.Unix(1672531200, 0).UTC(),
+				NotValidAfter:  time.Unix(1767225600, 0).UTC(),
+				Source:         "/Library/Keychains/System.keychain",
+			},
+			{
+				HostID:         1,
+				SHA1:           "abcdef0123456789abcdef0123456789abcdef01",
+				CommonName:     strings.Repeat("z", 255),
+				Issuer:         "/CN=Example Root CA",
+				NotValidBefore: time.Unix(1672531200, 0).UTC(),
+				NotValidAfter:  time.Unix(1704067200, 0).UTC(),
+				Source:         `LocalMachine\Personal`,
+			},
+		}, certs)
+		return nil
+	}
+
+	host := fleet.Host{
+		ID: 1,
+	}
+
+	err := directIngestCertificates(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{
+			"sha1": "0123456789abcdef0123456789abcdef01234567", "common_name": "Example Root CA", "subject": "/CN=Example Root CA",
+			"issuer": "/CN=Example Root CA", "ca": "1", "self_signed": "1", "not_valid_before": "1672531200", "not_valid_after": "1767225600",
+			"path": "/Library/Keychains/System.keychain",
+		},
+		{
+			// the fingerprint is normalized to lowercase
+			"sha1": "ABCDEF0123456789ABCDEF0123456789ABCDEF01", "common_name": strings.Repeat("z", 300), "subject": "",
+			"issuer": "/CN=Example Root CA", "ca": "0", "self_signed": "0", "not_valid_before": "1672531200.0", "not_valid_after": "1704067200",
+			"path": `LocalMachine\Personal`,
+		},
+		// invalid rows are ignored
+		{"sha1": "not-a-sha1", "not_valid_before": "1672531200", "not_valid_after": "1704067200"},
+		{"sha1": "0123456789abcdef0123456789abcdef0123456a", "not_valid_before": "", "not_valid_after": "1704067200"},
+	})
+
+	require.NoError(t, err)
+	require.True(t, ds.ReplaceHostCertificatesFuncInvoked)
+}
+
+func TestDirectIngestCloudInstanceMetadata(t *testing.T) {
+	ds := new(mock.Store)
+	var got *fleet.HostCloudInstance
+	ds.SetOrUpdateHostCloudInstanceFunc = func(ctx context.Context, hostID uint, instance *fleet.HostCloudInstance) error {
+		require.Equal(t, uint(1), hostID)
+		got = instance
+		return nil
+	}
+
+	host := fleet.Host{
+		ID: 1,
+	}
+
+	err := directIngestCloudInstanceMetadata(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{
+			"provider": "aws", "instance_id": "i-0abc", "region": "us-east-1", "account_id": "123456789012",
+			"tags": `{"Name": "web-1", "env": "prod"}`,
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, ds.SetOrUpdateHostCloudInstanceFuncInvoked)
+	require.Equal(t, &fleet.HostCloudInstance{
+		HostID:     1,
+		Provider:   fleet.CloudProviderAWS,
+		InstanceID: "i-0abc",
+		Region:     "us-east-1",
+		AccountID:  "123456789012",
+		Tags:       fleet.CloudInstanceTags{"Name": "web-1", "env": "prod"},
+	}, got)
+
+	// invalid tags are ignored
+	err = directIngestCloudInstanceMetadata(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"provider": "gcp", "instance_id": "8862617829912345678", "region": "us-central1", "account_id": "my-project", "tags": "{"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.CloudProviderGCP, got.Provider)
+	require.Nil(t, got.Tags)
+
+	// no row means the host is not a cloud instance
+	err = directIngestCloudInstanceMetadata(context.Background(), log.NewNopLogger(), &host, ds, nil)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	// rows with an unknown provider are skipped
+	ds.SetOrUpdateHostCloudInstanceFuncInvoked = false
+	err = directIngestCloudInstanceMetadata(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"provider": "oracle", "instance_id": "ocid1.instance"},
+	})
+	require.NoError(t, err)
+	require.False(t, ds.SetOrUpdateHostCloudInstanceFuncInvoked)
+}
+
 func TestDirectIngestOSWindows(t *testing.T) {
 	ds := new(mock.Store)
 
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/worker"
+)
+
+type dryRunPolicyAutomationRequest struct {
+	PolicyID uint `url:"policy_id"`
+	fleet.PolicyAutomationDryRunPayload
+}
+
+type dryRunPolicyAutomationResponse struct {
+	*fleet.PolicyAutomationDryRunResult
+	Err error `json:"error,omitempty"`
+}
+
+func (r dryRunPolicyAutomationResponse) error() error { return r.Err }
+
+func dryRunPolicyAutomationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*dryRunPolicyAutomationRequest)
+	res, err := svc.DryRunPolicyAutomation(ctx, req.PolicyID, req.PolicyAutomationDryRunPayload)
+	if err != nil {
+		return dryRunPolicyAutomationResponse{Err: err}, nil
+	}
+	return dryRunPolicyAutomationResponse{PolicyAutomationDryRunResult: res}, nil
+}
+
+func (svc *Service) DryRunPolicyAutomation(ctx context.Context, policyID uint, payload fleet.PolicyAutomationDryRunPayload) (*fleet.PolicyAutomationDryRunResult, error) {
+	// load the policy without authorization to get its team
+	svc.authz.SkipAuthorization(ctx)
+	policy, err := svc.ds.Policy(ctx, policyID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get policy")
+	}
+
+	// simulating an automation requires the permissions to configure it, and it
+	// lists the failing hosts of the policy.
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{PolicyData: fleet.PolicyData{TeamID: policy.TeamID}}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: policy.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+
+	// the automation settings of the policy are the team's for a team policy,
+	// with its integrations mapped to the global ones.
+	webhookSettings := appConfig.WebhookSettings.FailingPoliciesWebhook
+	intgs := appConfig.Integrations
+	if policy.TeamID != nil {
+		team, err := svc.ds.Team(ctx, *policy.TeamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+		team.ApplyInheritedSettings(appConfig)
+		webhookSettings = team.Config.WebhookSettings.FailingPoliciesWebhook
+		intgs, err = team.Config.Integrations.MatchWithIntegrations(appConfig.Integrations)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "map team integrations to global integrations")
+		}
+	}
+
+	activeAutomation := fleet.ActiveFailingPolicyAutomation(webhookSettings, intgs)
+	automationType := payload.AutomationType
+	if automationType == "" {
+		if activeAutomation == "" {
+			return nil, fleet.NewInvalidArgumentError("automation_type", "no automation is enabled for failing policies, an automation type must be provided")
+		}
+		automationType = activeAutomation
+	}
+
+	res := &fleet.PolicyAutomationDryRunResult{
+		PolicyID:       policy.ID,
+		AutomationType: automationType,
+	}
+	if automationType == activeAutomation {
+		for _, id := range webhookSettings.PolicyIDs {
+			if id == policy.ID {
+				res.PolicyEnabled = true
+				break
+			}
+		}
+	}
+
+	switch automationType {
+	case fleet.PolicyAutomationWebhook:
+		destURL := payload.DestinationURL
+		if destURL == "" {
+			destURL = webhookSettings.DestinationURL
+		}
+		if destURL == "" {
+			return nil, fleet.NewInvalidArgumentError("destination_url", "no destination URL is configured for the failing policies webhook")
+		}
+		if _, err := url.Parse(destURL); err != nil {
+			return nil, fleet.NewInvalidArgumentError("destination_url", err.Error())
+		}
+		hostBatchSize := webhookSettings.HostBatchSize
+		if payload.HostBatchSize != nil {
+			hostBatchSize = *payload.HostBatchSize
+		}
+		if hostBatchSize < 0 {
+			return nil, fleet.NewInvalidArgumentError("host_batch_size", "must be a positive number")
+		}
+
+		hosts, err := svc.ds.ListPolicyFailingHosts(ctx, policy.ID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "list policy failing hosts")
+		}
+		res.HostCount = len(hosts)
+
+		serverURL, err := url.Parse(appConfig.ServerSettings.ServerURL)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "parse server url")
+		}
+		hostIDs := make([]uint, 0, len(hosts))
+		for _, host := range hosts {
+			hostIDs = append(hostIDs, host.ID)
+		}
+		healthScores, err := svc.ds.GetHostHealthScores(ctx, hostIDs)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get host health scores")
+		}
+		now := time.Now()
+		for _, batch := range fleet.BatchPolicySetHosts(hosts, hostBatchSize) {
+			res.WebhookRequests = append(res.WebhookRequests, fleet.PolicyAutomationDryRunWebhookRequest{
+				URL:     destURL,
+				Payload: fleet.NewFailingPoliciesWebhookPayload(policy, batch, healthScores, serverURL, now),
+			})
+		}
+
+	case fleet.PolicyAutomationJira, fleet.PolicyAutomationZendesk:
+		if payload.DestinationURL != "" || payload.HostBatchSize != nil {
+			return nil, fleet.NewInvalidArgumentError("automation_type", "destination_url and host_batch_size are only supported for the webhook automation")
+		}
+
+		hosts, err := svc.ds.ListPolicyFailingHosts(ctx, policy.ID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "list policy failing hosts")
+		}
+		res.HostCount = len(hosts)
+		if len(hosts) == 0 {
+			// no ticket is created if no host fails the policy
+			break
+		}
+
+		render := worker.RenderJiraFailingPolicyIssue
+		if automationType == fleet.PolicyAutomationZendesk {
+			render = worker.RenderZendeskFailingPolicyTicket
+		}
+		summary, description, err := render(appConfig.ServerSettings.ServerURL, policy, hosts)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "render failing policy ticket")
+		}
+		res.Tickets = []fleet.PolicyAutomationDryRunTicket{{Summary: summary, Description: description}}
+
+	default:
+		return nil, fleet.NewInvalidArgumentError("automation_type", "must be one of webhook, jira or zendesk")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunPolicyAutomationAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		if id == 1 {
+			return &fleet.Policy{PolicyData: fleet.PolicyData{ID: id}}, nil
+		}
+		return &fleet.Policy{PolicyData: fleet.PolicyData{ID: id, TeamID: ptr.Uint(1)}}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.ListPolicyFailingHostsFunc = func(ctx context.Context, policyID uint) ([]fleet.PolicySetHost, error) {
+		return nil, nil
+	}
+	ds.GetHostHealthScoresFunc = func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name             string
+		user             *fleet.User
+		shouldFailGlobal bool
+		shouldFailTeam   bool
+	}{
+		{
+			name: "global admin",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+		},
+		{
+			name: "global maintainer",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+		},
+		{
+			name:             "global observer",
+			user:             &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailGlobal: true,
+			shouldFailTeam:   true,
+		},
+		{
+			name:             "team maintainer, belongs to team",
+			user:             &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			shouldFailGlobal: true,
+		},
+		{
+			name:             "team observer, belongs to team",
+			user:             &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailGlobal: true,
+			shouldFailTeam:   true,
+		},
+		{
+			name:             "team maintainer, DOES NOT belong to team",
+			user:             &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			shouldFailGlobal: true,
+			shouldFailTeam:   true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			payload := fleet.PolicyAutomationDryRunPayload{AutomationType: fleet.PolicyAutomationWebhook, DestinationURL: "https://example.com"}
+			_, err := svc.DryRunPolicyAutomation(ctx, 1, payload)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+			_, err = svc.DryRunPolicyAutomation(ctx, 2, payload)
+			checkAuthErr(t, tt.shouldFailTeam, err)
+		})
+	}
+}
+
+func TestDryRunPolicyAutomation(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	appConfig := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		return &fleet.Policy{PolicyData: fleet.PolicyData{ID: id, Name: "pol"}}, nil
+	}
+	hosts := []fleet.PolicySetHost{
+		{ID: 1, Hostname: "h1.local", DisplayName: "h1"},
+		{ID: 2, Hostname: "h2.local", DisplayName: "h2"},
+		{ID: 3, Hostname: "h3.local", DisplayName: "h3"},
+	}
+	ds.ListPolicyFailingHostsFunc = func(ctx context.Context, policyID uint) ([]fleet.PolicySetHost, error) {
+		return hosts, nil
+	}
+	ds.GetHostHealthScoresFunc = func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+		return map[uint]*fleet.HostHealthScore{2: {HostID: 2, Score: 40}}, nil
+	}
+
+	// no automation enabled and none provided
+	_, err := svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{})
+	require.ErrorContains(t, err, "automation_type")
+
+	// invalid automation type
+	_, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{AutomationType: "email"})
+	require.ErrorContains(t, err, "automation_type")
+
+	// webhook without a destination
+	_, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{AutomationType: fleet.PolicyAutomationWebhook})
+	require.ErrorContains(t, err, "destination_url")
+
+	// simulate a webhook that is not enabled, in batches of 2 hosts
+	res, err := svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{
+		AutomationType: fleet.PolicyAutomationWebhook,
+		DestinationURL: "https://example.com/hook",
+		HostBatchSize:  ptr.Int(2),
+	})
+	require.NoError(t, err)
+	require.False(t, res.PolicyEnabled)
+	require.Equal(t, 3, res.HostCount)
+	require.Len(t, res.WebhookRequests, 2)
+	require.Equal(t, "https://example.com/hook", res.WebhookRequests[0].URL)
+	require.Equal(t, []fleet.FailingPoliciesWebhookHost{
+		{ID: 1, Hostname: "h1.local", DisplayName: "h1", URL: "https://fleet.example.com/hosts/1"},
+		{ID: 2, Hostname: "h2.local", DisplayName: "h2", URL: "https://fleet.example.com/hosts/2", HealthScore: ptr.Int(40)},
+	}, res.WebhookRequests[0].Payload.FailingHosts)
+	require.Equal(t, []fleet.FailingPoliciesWebhookHost{
+		{ID: 3, Hostname: "h3.local", DisplayName: "h3", URL: "https://fleet.example.com/hosts/3"},
+	}, res.WebhookRequests[1].Payload.FailingHosts)
+	require.Equal(t, uint(1), res.WebhookRequests[0].Payload.Policy.ID)
+	require.Empty(t, res.Tickets)
+
+	// the enabled webhook is used by default
+	appConfig.WebhookSettings.FailingPoliciesWebhook = fleet.FailingPoliciesWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://example.com/configured",
+		PolicyIDs:      []uint{1},
+	}
+	res, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{})
+	require.NoError(t, err)
+	require.Equal(t, fleet.PolicyAutomationWebhook, res.AutomationType)
+	require.True(t, res.PolicyEnabled)
+	require.Len(t, res.WebhookRequests, 1)
+	require.Equal(t, "https://example.com/configured", res.WebhookRequests[0].URL)
+	require.Len(t, res.WebhookRequests[0].Payload.FailingHosts, 3)
+
+	// the policy is not enabled for another automation type
+	res, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{AutomationType: fleet.PolicyAutomationJira})
+	require.NoError(t, err)
+	require.False(t, res.PolicyEnabled)
+	require.Equal(t, 3, res.HostCount)
+	require.Empty(t, res.WebhookRequests)
+	require.Len(t, res.Tickets, 1)
+	require.Contains(t, res.Tickets[0].Summary, "pol")
+	require.Contains(t, res.Tickets[0].Description, "https://fleet.example.com/hosts/1")
+
+	// webhook settings are not supported for tickets
+	_, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{AutomationType: fleet.PolicyAutomationZendesk, HostBatchSize: ptr.Int(1)})
+	require.ErrorContains(t, err, "only supported for the webhook")
+
+	// no ticket is created without failing hosts
+	hosts = nil
+	res, err = svc.DryRunPolicyAutomation(ctx, 1, fleet.PolicyAutomationDryRunPayload{AutomationType: fleet.PolicyAutomationZendesk})
+	require.NoError(t, err)
+	require.Zero(t, res.HostCount)
+	require.Empty(t, res.Tickets)
+
+	// team policies use the team's automation with its integrations mapped to
+	// the global ones
+	appConfig.WebhookSettings.FailingPoliciesWebhook = fleet.FailingPoliciesWebhookSettings{}
+	appConfig.Integrations.Jira = []*fleet.JiraIntegration{{URL: "https://jira.example.com", ProjectKey: "P"}}
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		return &fleet.Policy{PolicyData: fleet.PolicyData{ID: id, Name: "pol", TeamID: ptr.Uint(1)}}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{
+			WebhookSettings: fleet.TeamWebhookSettings{FailingPoliciesWebhook: fleet.FailingPoliciesWebhookSettings{PolicyIDs: []uint{2}}},
+			Integrations: fleet.TeamIntegrations{
+				Jira: []*fleet.TeamJiraIntegration{{URL: "https://jira.example.com", ProjectKey: "P", EnableFailingPolicies: true}},
+			},
+		}}, nil
+	}
+	hosts = []fleet.PolicySetHost{{ID: 1, Hostname: "h1.local", DisplayName: "h1"}}
+	res, err = svc.DryRunPolicyAutomation(ctx, 2, fleet.PolicyAutomationDryRunPayload{})
+	require.NoError(t, err)
+	require.Equal(t, fleet.PolicyAutomationJira, res.AutomationType)
+	require.True(t, res.PolicyEnabled)
+	require.Len(t, res.Tickets, 1)
+}
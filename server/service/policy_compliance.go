@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get policy compliance trends
+////////////////////////////////////////////////////////////////////////////////
+
+type getPolicyComplianceTrendsRequest struct {
+	TeamID    *uint  `query:"team_id,optional"`
+	PolicyID  *uint  `query:"policy_id,optional"`
+	StartDate string `query:"start_date,optional"`
+	EndDate   string `query:"end_date,optional"`
+}
+
+type getPolicyComplianceTrendsResponse struct {
+	*fleet.PolicyComplianceTrends
+	Err error `json:"error,omitempty"`
+}
+
+func (r getPolicyComplianceTrendsResponse) error() error { return r.Err }
+
+func getPolicyComplianceTrendsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getPolicyComplianceTrendsRequest)
+
+	opts := fleet.PolicyComplianceTrendsOptions{TeamID: req.TeamID, PolicyID: req.PolicyID}
+	var err error
+	if req.StartDate != "" {
+		if opts.StartDate, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			setAuthCheckedOnPreAuthErr(ctx)
+			return getPolicyComplianceTrendsResponse{Err: fleet.NewInvalidArgumentError("start_date", "must be a date in the YYYY-MM-DD format")}, nil
+		}
+	}
+	if req.EndDate != "" {
+		if opts.EndDate, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			setAuthCheckedOnPreAuthErr(ctx)
+			return getPolicyComplianceTrendsResponse{Err: fleet.NewInvalidArgumentError("end_date", "must be a date in the YYYY-MM-DD format")}, nil
+		}
+	}
+
+	trends, err := svc.GetPolicyComplianceTrends(ctx, opts)
+	if err != nil {
+		return getPolicyComplianceTrendsResponse{Err: err}, nil
+	}
+	return getPolicyComplianceTrendsResponse{PolicyComplianceTrends: trends}, nil
+}
+
+func (svc *Service) GetPolicyComplianceTrends(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) (*fleet.PolicyComplianceTrends, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{
+		PolicyData: fleet.PolicyData{
+			TeamID: opts.TeamID,
+		},
+	}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if opts.TeamID != nil {
+		if _, err := svc.ds.Team(ctx, *opts.TeamID); err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "loading team %d", *opts.TeamID)
+		}
+	}
+
+	// the current day is included by default
+	today := svc.clock.Now().UTC().Truncate(24 * time.Hour)
+	if opts.EndDate.IsZero() {
+		opts.EndDate = today
+	}
+	if opts.StartDate.IsZero() {
+		opts.StartDate = opts.EndDate.AddDate(0, 0, -(fleet.PolicyComplianceTrendsDefaultDays - 1))
+	}
+	opts.StartDate = opts.StartDate.UTC().Truncate(24 * time.Hour)
+	opts.EndDate = opts.EndDate.UTC().Truncate(24 * time.Hour)
+
+	if opts.StartDate.After(opts.EndDate) {
+		return nil, fleet.NewInvalidArgumentError("start_date", "must not be after end_date")
+	}
+	if opts.StartDate.Before(today.Add(-fleet.PolicyComplianceHistoryRetention)) {
+		return nil, fleet.NewInvalidArgumentError("start_date", "must be within the last 730 days")
+	}
+
+	days, err := svc.ds.GetPolicyComplianceTrends(ctx, opts)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get policy compliance trends")
+	}
+	return &fleet.PolicyComplianceTrends{
+		TeamID:    opts.TeamID,
+		PolicyID:  opts.PolicyID,
+		StartDate: opts.StartDate,
+		EndDate:   opts.EndDate,
+		Days:      days,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPolicyComplianceTrends(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	var gotOpts fleet.PolicyComplianceTrendsOptions
+	ds.GetPolicyComplianceTrendsFunc = func(ctx context.Context, opts fleet.PolicyComplianceTrendsOptions) ([]fleet.PolicyComplianceDay, error) {
+		gotOpts = opts
+		return []fleet.PolicyComplianceDay{}, nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		teamID     *uint
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}, nil, false},
+		{"global observer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}, nil, false},
+		{"global observer, team", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}, ptr.Uint(1), false},
+		{"team observer", &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, ptr.Uint(1), false},
+		{"team observer, other team", &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, ptr.Uint(2), true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			_, err := svc.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{TeamID: tt.teamID})
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	// the trends cover the last days by default, including the current one
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	trends, err := svc.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, today, trends.EndDate)
+	require.Equal(t, today.AddDate(0, 0, -(fleet.PolicyComplianceTrendsDefaultDays-1)), trends.StartDate)
+	require.Equal(t, trends.StartDate, gotOpts.StartDate)
+	require.Equal(t, trends.EndDate, gotOpts.EndDate)
+	require.NotNil(t, trends.Days)
+
+	end := today.AddDate(0, 0, -10)
+	_, err = svc.GetPolicyComplianceTrends(ctx, fleet.PolicyComplianceTrendsOptions{EndDate: end, PolicyID: ptr.Uint(3)})
+	require.NoError(t, err)
+	require.Equal(t, end.AddDate(0, 0, -(fleet.PolicyComplianceTrendsDefaultDays-1)), gotOpts.StartDate)
+	require.Equal(t, ptr.Uint(3), gotOpts.PolicyID)
+
+	for _, opts := range []fleet.PolicyComplianceTrendsOptions{
+		{StartDate: today, EndDate: today.AddDate(0, 0, -1)},
+		{StartDate: today.AddDate(-3, 0, 0)},
+	} {
+		_, err = svc.GetPolicyComplianceTrends(ctx, opts)
+		var invalid *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &invalid)
+	}
+}
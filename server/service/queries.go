@@ -12,6 +12,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/service/query_validation"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -585,6 +586,50 @@ func (svc *Service) DeleteQueries(ctx context.Context, ids []uint) (uint, error)
 	return n, nil
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Validate Query
+////////////////////////////////////////////////////////////////////////////////
+
+type validateQueryRequest struct {
+	fleet.QueryValidationPayload
+}
+
+type validateQueryResponse struct {
+	*fleet.QueryValidationResult
+	Err error `json:"error,omitempty"`
+}
+
+func (r validateQueryResponse) error() error { return r.Err }
+
+func validateQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*validateQueryRequest)
+	res, err := svc.ValidateQuery(ctx, req.QueryValidationPayload)
+	if err != nil {
+		return validateQueryResponse{Err: err}, nil
+	}
+	return validateQueryResponse{QueryValidationResult: res}, nil
+}
+
+func (svc *Service) ValidateQuery(ctx context.Context, p fleet.QueryValidationPayload) (*fleet.QueryValidationResult, error) {
+	// The query is not stored nor run, any user that can read queries can
+	// validate one.
+	if err := svc.authz.Authorize(ctx, &fleet.Query{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: fmt.Sprintf("query payload verification: %s", err),
+		})
+	}
+
+	res, err := query_validation.Validate(ctx, p.Query, p.Platforms())
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate query")
+	}
+	return res, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Apply Query Specs
 ////////////////////////////////////////////////////////////////////////////////
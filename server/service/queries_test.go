@@ -777,3 +777,51 @@ func TestComparePlatforms(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateQuery(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	teamObserver := &fleet.User{
+		ID:    42,
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}},
+	}
+	teamGitOps := &fleet.User{
+		ID:    43,
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleGitOps}},
+	}
+
+	for _, tt := range []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, false},
+		{"team observer", teamObserver, false},
+		{"team gitops", teamGitOps, true},
+		{"user without roles", &fleet.User{ID: 44}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			_, err := svc.ValidateQuery(ctx, fleet.QueryValidationPayload{Query: "SELECT 1"})
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.ValidateQuery(ctx, fleet.QueryValidationPayload{Query: ""})
+	var badRequest *fleet.BadRequestError
+	require.ErrorAs(t, err, &badRequest)
+
+	_, err = svc.ValidateQuery(ctx, fleet.QueryValidationPayload{Query: "SELECT 1", Platform: "foobar"})
+	require.ErrorAs(t, err, &badRequest)
+
+	res, err := svc.ValidateQuery(ctx, fleet.QueryValidationPayload{Query: "SELECT gid FROM users", Platform: "darwin,chrome"})
+	require.NoError(t, err)
+	require.False(t, res.Valid)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, fleet.QueryValidationPlatformMismatch, res.Errors[0].Type)
+	require.Equal(t, "chrome", res.Errors[0].Platform)
+}
@@ -0,0 +1,361 @@
+// Package query_validation validates osquery SQL queries against the schema
+// of the osquery and fleetd tables, so that invalid queries are caught before
+// they are deployed to the hosts.
+//
+// The queries are compiled by SQLite (the SQL engine of osquery) in an
+// in-memory database where the tables referenced by the query are created
+// from the schema as they are needed.
+package query_validation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/schema"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/osquery_utils"
+	"github.com/mattn/go-sqlite3"
+)
+
+const driverName = "sqlite3_osquery_validation"
+
+// osqueryFunctions are the SQL functions that osquery adds to SQLite, see
+// https://osquery.readthedocs.io/en/stable/introduction/sql/#additional-functions
+var osqueryFunctions = []string{
+	// math
+	"sqrt", "log", "log10", "ln", "exp", "power", "pi", "ceil", "floor",
+	"sin", "cos", "tan", "cot", "asin", "acos", "atan", "degrees", "radians",
+	// string
+	"split", "regex_split", "regex_match", "inet_aton", "concat", "concat_ws",
+	"version_compare",
+	// encoding and hashing
+	"to_base64", "from_base64", "conditional_to_base64", "sha1", "sha256", "md5",
+	// network
+	"community_id_v1", "in_cidr_block",
+	// carving
+	"carve",
+}
+
+// sqliteRecursive is the authorizer action of recursive common table
+// expressions, it is not defined by the driver.
+const sqliteRecursive = 33
+
+// maxCompilations is the maximum number of times the query is compiled,
+// each compilation creates a table or adds an unknown column.
+const maxCompilations = 64
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// the functions are only needed to compile the queries.
+			stub := func(args ...interface{}) interface{} { return nil }
+			for _, name := range osqueryFunctions {
+				if err := conn.RegisterFunc(name, stub, true); err != nil {
+					return fmt.Errorf("register function %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// Validate validates the query for the targeted platforms (all platforms if
+// empty).
+func Validate(ctx context.Context, query string, platforms []string) (*fleet.QueryValidationResult, error) {
+	tables, err := schema.Tables()
+	if err != nil {
+		return nil, fmt.Errorf("load osquery schema: %w", err)
+	}
+
+	db, err := sql.Open(driverName, ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get sqlite connection: %w", err)
+	}
+	defer conn.Close()
+
+	var c compilation
+	if err := conn.Raw(func(driverConn any) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected sqlite connection type %T", driverConn)
+		}
+		c, err = compile(sqliteConn, tables, query)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return c.result(tables, platforms), nil
+}
+
+// compilation is the outcome of the compilation of a query.
+type compilation struct {
+	// compiled is true if the query compiled once the unknown tables and
+	// columns were added.
+	compiled bool
+	// err is the error that prevented the compilation of the query, nil if it
+	// compiled or if it references an unknown column that could not be added.
+	err error
+	// notSelect is true if the query does something else than reading tables.
+	notSelect bool
+	// reads are the columns read by the query, by table.
+	reads map[string]map[string]bool
+	// created are the tables created for the query, in order.
+	created []string
+	// unknownTables are the tables referenced by the query that are not in
+	// the schema.
+	unknownTables []string
+	// unknownColumns are the columns referenced by the query that are not in
+	// the schema.
+	unknownColumns []string
+	// addedColumns are the unknown columns added to the tables.
+	addedColumns map[string]map[string]bool
+}
+
+func compile(conn *sqlite3.SQLiteConn, tables map[string]schema.Table, query string) (compilation, error) {
+	c := compilation{addedColumns: make(map[string]map[string]bool)}
+	unknownTables := make(map[string]bool)
+
+	for i := 0; i < maxCompilations; i++ {
+		c.reads = make(map[string]map[string]bool)
+		c.notSelect = false
+		conn.RegisterAuthorizer(func(action int, arg1, arg2, _ string) int {
+			switch action {
+			case sqlite3.SQLITE_READ:
+				if c.reads[arg1] == nil {
+					c.reads[arg1] = make(map[string]bool)
+				}
+				c.reads[arg1][arg2] = true
+			case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+			default:
+				c.notSelect = true
+			}
+			return sqlite3.SQLITE_OK
+		})
+		stmt, err := conn.Prepare(query)
+		conn.RegisterAuthorizer(nil)
+		if err == nil {
+			stmt.Close()
+			c.compiled = true
+			return c, nil
+		}
+
+		msg := err.Error()
+		switch {
+		case strings.HasPrefix(msg, "no such table: "):
+			name := strings.ToLower(strings.TrimPrefix(msg, "no such table: "))
+			name = strings.TrimPrefix(name, "main.")
+			columns := []schema.Column{{Name: "_", Type: "text"}}
+			if t, ok := tables[name]; ok {
+				columns = t.Columns
+			} else {
+				unknownTables[name] = true
+				c.unknownTables = append(c.unknownTables, name)
+			}
+			if err := createTable(conn, name, columns); err != nil {
+				return c, err
+			}
+			c.created = append(c.created, name)
+
+		case strings.HasPrefix(msg, "no such column: "):
+			ref := strings.TrimPrefix(msg, "no such column: ")
+			column := ref[strings.LastIndex(ref, ".")+1:]
+			// the table of the column is not known, the column is added to the
+			// created tables until the query compiles (unknown tables first, as
+			// their columns are not reported).
+			var table string
+			for _, unknown := range []bool{true, false} {
+				for _, name := range c.created {
+					if unknownTables[name] == unknown && !c.addedColumns[name][column] {
+						table = name
+						break
+					}
+				}
+				if table != "" {
+					break
+				}
+			}
+			if table == "" || !unknownTables[table] {
+				if !slices.Contains(c.unknownColumns, column) {
+					c.unknownColumns = append(c.unknownColumns, column)
+				}
+			}
+			if table == "" {
+				return c, nil
+			}
+			if _, err := conn.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, quoteIdentifier(table), quoteIdentifier(column)), nil); err != nil {
+				return c, fmt.Errorf("add column %s to table %s: %w", column, table, err)
+			}
+			if c.addedColumns[table] == nil {
+				c.addedColumns[table] = make(map[string]bool)
+			}
+			c.addedColumns[table][column] = true
+
+		default:
+			c.err = err
+			return c, nil
+		}
+	}
+	return c, nil
+}
+
+func createTable(conn *sqlite3.SQLiteConn, name string, columns []schema.Column) error {
+	defs := make([]string, 0, len(columns))
+	for _, col := range columns {
+		defs = append(defs, quoteIdentifier(col.Name)+" "+sqliteType(col.Type))
+	}
+	stmt := fmt.Sprintf(`CREATE TABLE %s (%s)`, quoteIdentifier(name), strings.Join(defs, ", "))
+	if _, err := conn.Exec(stmt, nil); err != nil {
+		return fmt.Errorf("create table %s: %w", name, err)
+	}
+	return nil
+}
+
+// sqliteType returns the SQLite type of the osquery column type.
+func sqliteType(osqueryType string) string {
+	switch osqueryType {
+	case "text":
+		return "TEXT"
+	case "double":
+		return "REAL"
+	default:
+		return "INTEGER"
+	}
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (c compilation) result(tables map[string]schema.Table, platforms []string) *fleet.QueryValidationResult {
+	res := &fleet.QueryValidationResult{
+		Errors:           []fleet.QueryValidationIssue{},
+		Warnings:         []fleet.QueryValidationIssue{},
+		Tables:           []string{},
+		DiscoveryQueries: []fleet.QueryDiscoveryQuery{},
+	}
+
+	if c.err != nil {
+		res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+			Type:    fleet.QueryValidationSyntaxError,
+			Message: c.err.Error(),
+		})
+	}
+	if c.notSelect {
+		res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+			Type:    fleet.QueryValidationNotSelect,
+			Message: "only SELECT statements are supported by osquery",
+		})
+	}
+	for _, name := range c.unknownTables {
+		res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+			Type:    fleet.QueryValidationUnknownTable,
+			Table:   name,
+			Message: fmt.Sprintf("no such table: %s", name),
+		})
+	}
+	for _, column := range c.unknownColumns {
+		res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+			Type:    fleet.QueryValidationUnknownColumn,
+			Column:  column,
+			Message: fmt.Sprintf("no such column: %s", column),
+		})
+	}
+
+	// the tables read by the query, or created for it if it did not compile.
+	used := make(map[string]bool)
+	for name := range c.reads {
+		used[strings.ToLower(name)] = true
+	}
+	if !c.compiled {
+		for _, name := range c.created {
+			used[name] = true
+		}
+	}
+	for name := range used {
+		if _, ok := tables[name]; ok {
+			res.Tables = append(res.Tables, name)
+		}
+	}
+	sort.Strings(res.Tables)
+
+	compatible := map[string]bool{"darwin": true, "windows": true, "linux": true, "chrome": true}
+	for _, name := range res.Tables {
+		t := tables[name]
+		for platform := range compatible {
+			if !t.SupportsPlatform(platform) {
+				delete(compatible, platform)
+			}
+		}
+
+		for _, platform := range platforms {
+			if !t.SupportsPlatform(platform) {
+				res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+					Type:     fleet.QueryValidationPlatformMismatch,
+					Table:    name,
+					Platform: platform,
+					Message:  fmt.Sprintf("table %s is not available on %s", name, platform),
+				})
+				continue
+			}
+			for _, col := range t.Columns {
+				if c.reads[name][col.Name] && !col.SupportsPlatform(platform) {
+					res.Errors = append(res.Errors, fleet.QueryValidationIssue{
+						Type:     fleet.QueryValidationPlatformMismatch,
+						Table:    name,
+						Column:   col.Name,
+						Platform: platform,
+						Message:  fmt.Sprintf("column %s of table %s is not available on %s", col.Name, name, platform),
+					})
+				}
+			}
+		}
+
+		var required []string
+		var constrained bool
+		for _, col := range t.Columns {
+			if col.Required {
+				required = append(required, col.Name)
+				constrained = constrained || c.reads[name][col.Name]
+			}
+		}
+		if len(required) > 0 && !constrained && c.compiled {
+			res.Warnings = append(res.Warnings, fleet.QueryValidationIssue{
+				Type:  fleet.QueryValidationMissingRequiredColumn,
+				Table: name,
+				Message: fmt.Sprintf("table %s returns no rows without a constraint on one of its required columns: %s",
+					name, strings.Join(required, ", ")),
+			})
+		}
+		if t.Evented {
+			res.Warnings = append(res.Warnings, fleet.QueryValidationIssue{
+				Type:    fleet.QueryValidationEventedTable,
+				Table:   name,
+				Message: fmt.Sprintf("table %s is evented, it only returns rows if osquery events are enabled", name),
+			})
+		}
+		if !t.IsCoreOsquery() {
+			res.DiscoveryQueries = append(res.DiscoveryQueries, fleet.QueryDiscoveryQuery{
+				Table: name,
+				Query: osquery_utils.DiscoveryTable(name),
+			})
+		}
+	}
+
+	res.Platforms = make([]string, 0, len(compatible))
+	for platform := range compatible {
+		res.Platforms = append(res.Platforms, platform)
+	}
+	sort.Strings(res.Platforms)
+
+	res.Valid = len(res.Errors) == 0
+	return res
+}
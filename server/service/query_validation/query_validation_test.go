@@ -0,0 +1,110 @@
+package query_validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		res, err := Validate(ctx, `WITH cached_groups AS (SELECT * FROM groups)
+			SELECT uid, username, groupname FROM users LEFT JOIN cached_groups USING (gid)
+			WHERE regex_match(shell, '/bin/.*', 0) IS NOT NULL`, nil)
+		require.NoError(t, err)
+		require.True(t, res.Valid)
+		require.Empty(t, res.Errors)
+		require.Empty(t, res.Warnings)
+		require.Equal(t, []string{"groups", "users"}, res.Tables)
+		require.Equal(t, []string{"darwin", "linux", "windows"}, res.Platforms)
+		require.Empty(t, res.DiscoveryQueries)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		res, err := Validate(ctx, `SELEC * FROM users`, nil)
+		require.NoError(t, err)
+		require.False(t, res.Valid)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, fleet.QueryValidationSyntaxError, res.Errors[0].Type)
+		require.Contains(t, res.Errors[0].Message, "syntax error")
+	})
+
+	t.Run("not select", func(t *testing.T) {
+		res, err := Validate(ctx, `DELETE FROM users`, nil)
+		require.NoError(t, err)
+		require.False(t, res.Valid)
+		require.Equal(t, []fleet.QueryValidationIssue{{
+			Type:    fleet.QueryValidationNotSelect,
+			Message: "only SELECT statements are supported by osquery",
+		}}, res.Errors)
+	})
+
+	t.Run("unknown tables and columns", func(t *testing.T) {
+		res, err := Validate(ctx, `SELECT u.username, u.nickname, f.color FROM users u JOIN foobar f ON f.uid = u.uid JOIN barbaz`, nil)
+		require.NoError(t, err)
+		require.False(t, res.Valid)
+		require.ElementsMatch(t, []fleet.QueryValidationIssue{
+			{Type: fleet.QueryValidationUnknownTable, Table: "foobar", Message: "no such table: foobar"},
+			{Type: fleet.QueryValidationUnknownTable, Table: "barbaz", Message: "no such table: barbaz"},
+			{Type: fleet.QueryValidationUnknownColumn, Column: "nickname", Message: "no such column: nickname"},
+		}, res.Errors)
+		require.Equal(t, []string{"users"}, res.Tables)
+	})
+
+	t.Run("platforms", func(t *testing.T) {
+		res, err := Validate(ctx, `SELECT uid, username FROM users`, []string{"darwin", "chrome"})
+		require.NoError(t, err)
+		require.True(t, res.Valid)
+
+		res, err = Validate(ctx, `SELECT gid, username FROM users`, []string{"darwin", "chrome"})
+		require.NoError(t, err)
+		require.False(t, res.Valid)
+		require.Equal(t, []fleet.QueryValidationIssue{{
+			Type:     fleet.QueryValidationPlatformMismatch,
+			Table:    "users",
+			Column:   "gid",
+			Platform: "chrome",
+			Message:  "column gid of table users is not available on chrome",
+		}}, res.Errors)
+
+		res, err = Validate(ctx, `SELECT 1 FROM users JOIN disk_encryption WHERE encrypted = 1`, []string{"darwin", "windows"})
+		require.NoError(t, err)
+		require.False(t, res.Valid)
+		require.Equal(t, []fleet.QueryValidationIssue{{
+			Type:     fleet.QueryValidationPlatformMismatch,
+			Table:    "disk_encryption",
+			Platform: "windows",
+			Message:  "table disk_encryption is not available on windows",
+		}}, res.Errors)
+		require.Equal(t, []string{"darwin", "linux"}, res.Platforms)
+	})
+
+	t.Run("warnings and discovery", func(t *testing.T) {
+		res, err := Validate(ctx, `SELECT f.filename, p.path FROM file f, process_events p, macos_profiles mp`, nil)
+		require.NoError(t, err)
+		require.True(t, res.Valid)
+		require.ElementsMatch(t, []fleet.QueryValidationIssueType{
+			fleet.QueryValidationMissingRequiredColumn,
+			fleet.QueryValidationEventedTable,
+		}, []fleet.QueryValidationIssueType{res.Warnings[0].Type, res.Warnings[1].Type})
+		require.Equal(t, []fleet.QueryDiscoveryQuery{{
+			Table: "macos_profiles",
+			Query: "SELECT 1 FROM osquery_registry WHERE active = true AND registry = 'table' AND name = 'macos_profiles';",
+		}}, res.DiscoveryQueries)
+
+		res, err = Validate(ctx, `SELECT * FROM file WHERE path = '/etc/hosts'`, nil)
+		require.NoError(t, err)
+		require.Empty(t, res.Warnings)
+
+		// fleetd tables that are not in the merged schema yet are in the catalog
+		res, err = Validate(ctx, `SELECT device FROM smart_disk_health WHERE health = 'failed'`, nil)
+		require.NoError(t, err)
+		require.True(t, res.Valid)
+		require.Equal(t, []string{"smart_disk_health"}, res.Tables)
+		require.Len(t, res.DiscoveryQueries, 1)
+	})
+}
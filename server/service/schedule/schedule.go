@@ -46,6 +46,8 @@ type Schedule struct {
 
 	jobs []Job
 
+	jobErrorHandler JobErrorHandler
+
 	statsStore CronStatsStore
 }
 
@@ -74,10 +76,16 @@ type CronStatsStore interface {
 	GetLatestCronStats(ctx context.Context, name string) ([]fleet.CronStats, error)
 	// InsertCronStats inserts cron stats for the named cron schedule
 	InsertCronStats(ctx context.Context, statsType fleet.CronStatsType, name string, instance string, status fleet.CronStatsStatus) (int, error)
-	// UpdateCronStats updates the status of the identified cron stats record
-	UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus) error
+	// UpdateCronStats updates the status and the job errors of the identified cron stats record
+	UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error
+	// IsCronSchedulePaused returns true if the scheduled runs of the named cron schedule are paused
+	IsCronSchedulePaused(ctx context.Context, name string) (bool, error)
 }
 
+// JobErrorHandler is called with the error of a failed job, after it is
+// logged.
+type JobErrorHandler func(ctx context.Context, jobID string, err error)
+
 // Option allows configuring a Schedule.
 type Option func(*Schedule)
 
@@ -120,6 +128,14 @@ func WithJob(id string, fn JobFn) Option {
 	}
 }
 
+// WithJobErrorHandler sets a function called when a job of the Schedule
+// fails, e.g. to notify the admins.
+func WithJobErrorHandler(fn JobErrorHandler) Option {
+	return func(s *Schedule) {
+		s.jobErrorHandler = fn
+	}
+}
+
 // New creates and returns a Schedule.
 // Jobs are added with the WithJob Option.
 //
@@ -276,6 +292,18 @@ func (s *Schedule) Start() {
 					continue
 				}
 
+				paused, err := s.statsStore.IsCronSchedulePaused(s.ctx, s.name)
+				if err != nil {
+					level.Error(s.logger).Log("err", "get paused status", "details", err)
+					ctxerr.Handle(s.ctx, err)
+				}
+				if paused {
+					// skip the scheduled run, triggered runs still run
+					level.Info(s.logger).Log("msg", fmt.Sprintf("schedule is paused, wait %v", schedInterval))
+					schedTicker.Reset(schedInterval)
+					continue
+				}
+
 				ok, cancelHold := s.holdLock()
 				if !ok {
 					level.Debug(s.logger).Log("msg", "unable to acquire lock")
@@ -400,6 +428,11 @@ func (s *Schedule) Name() string {
 	return s.name
 }
 
+// Interval returns the current interval of the schedule.
+func (s *Schedule) Interval() time.Duration {
+	return s.getSchedInterval()
+}
+
 // runWithStats runs all jobs in the schedule. Prior to starting the run, it creates a
 // record in the database for the provided stats type with "pending" status. After completing the
 // run, the stats record is updated to "completed" status.
@@ -411,24 +444,45 @@ func (s *Schedule) runWithStats(statsType fleet.CronStatsType) {
 	}
 	level.Info(s.logger).Log("status", "pending")
 
-	s.runAllJobs()
+	cronErrors := s.runAllJobs()
 
-	if err := s.updateStats(statsID, fleet.CronStatsStatusCompleted); err != nil {
+	if err := s.updateStats(statsID, fleet.CronStatsStatusCompleted, cronErrors); err != nil {
 		level.Error(s.logger).Log("err", fmt.Sprintf("update cron stats %s", s.name), "details", err)
 		ctxerr.Handle(s.ctx, err)
 	}
 	level.Info(s.logger).Log("status", "completed")
 }
 
-// runAllJobs runs all jobs in the schedule.
-func (s *Schedule) runAllJobs() {
+// runAllJobs runs all jobs in the schedule and returns the errors of the
+// jobs that failed, by job ID.
+func (s *Schedule) runAllJobs() fleet.CronScheduleErrors {
+	var cronErrors fleet.CronScheduleErrors
 	for _, job := range s.jobs {
 		level.Debug(s.logger).Log("msg", "starting", "jobID", job.ID)
 		if err := runJob(s.ctx, job.Fn); err != nil {
 			level.Error(s.logger).Log("err", "running job", "details", err, "jobID", job.ID)
 			ctxerr.Handle(s.ctx, err)
+			if cronErrors == nil {
+				cronErrors = make(fleet.CronScheduleErrors)
+			}
+			cronErrors[job.ID] = err.Error()
+			if s.jobErrorHandler != nil {
+				s.handleJobError(job.ID, err)
+			}
 		}
 	}
+	return cronErrors
+}
+
+// handleJobError calls the job error handler with panic recovery, so that a
+// failing handler does not stop the schedule.
+func (s *Schedule) handleJobError(jobID string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			level.Error(s.logger).Log("err", "handling job error", "details", r, "jobID", jobID)
+		}
+	}()
+	s.jobErrorHandler(s.ctx, jobID, err)
 }
 
 // runJob executes the job function with panic recovery.
@@ -578,8 +632,8 @@ func (s *Schedule) insertStats(statsType fleet.CronStatsType, status fleet.CronS
 	return s.statsStore.InsertCronStats(s.ctx, statsType, s.name, s.instanceID, status)
 }
 
-func (s *Schedule) updateStats(id int, status fleet.CronStatsStatus) error {
-	return s.statsStore.UpdateCronStats(s.ctx, id, status)
+func (s *Schedule) updateStats(id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error {
+	return s.statsStore.UpdateCronStats(s.ctx, id, status, cronErrors)
 }
 
 func (s *Schedule) getLockName() string {
@@ -331,6 +331,96 @@ func TestJobPanicRecover(t *testing.T) {
 	}
 }
 
+func TestJobErrorHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	failedJobs := make(map[string]string)
+
+	ms := SetUpMockStatsStore("test_schedule", fleet.CronStats{
+		ID:        1,
+		StatsType: fleet.CronStatsTypeScheduled,
+		Name:      "test_schedule",
+		Instance:  "test_instance",
+		CreatedAt: time.Now().Truncate(1 * time.Second).Add(-1 * time.Second),
+		UpdatedAt: time.Now().Truncate(1 * time.Second).Add(-1 * time.Second),
+		Status:    fleet.CronStatsStatusCompleted,
+	})
+	s := New(ctx, "test_schedule", "test_instance", 1*time.Second, NopLocker{}, ms,
+		WithJob("job_1", func(ctx context.Context) error {
+			return errors.New("job_1 failed")
+		}),
+		WithJob("job_2", func(ctx context.Context) error {
+			return nil
+		}),
+		WithJob("job_3", func(ctx context.Context) error {
+			panic("job_3 panicked")
+		}),
+		WithJobErrorHandler(func(ctx context.Context, jobID string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failedJobs[jobID] = err.Error()
+		}))
+	s.Start()
+
+	time.Sleep(1200 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-s.Done():
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, map[string]string{"job_1": "job_1 failed", "job_3": "job_3 panicked"}, failedJobs)
+
+		// the errors are recorded in the stats of the run
+		ms.Lock()
+		defer ms.Unlock()
+		require.Len(t, ms.stats, 2)
+		require.Equal(t, fleet.CronScheduleErrors{"job_1": "job_1 failed", "job_3": "job_3 panicked"}, ms.stats[2].Errors)
+	case <-time.After(5 * time.Second):
+		t.Error("timeout")
+	}
+}
+
+func TestSchedulePaused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ms := SetUpMockStatsStore("test_schedule", fleet.CronStats{
+		ID:        1,
+		StatsType: fleet.CronStatsTypeScheduled,
+		Name:      "test_schedule",
+		Instance:  "test_instance",
+		CreatedAt: time.Now().Truncate(1 * time.Second).Add(-1 * time.Second),
+		UpdatedAt: time.Now().Truncate(1 * time.Second).Add(-1 * time.Second),
+		Status:    fleet.CronStatsStatusCompleted,
+	})
+	ms.SetPaused(true)
+
+	var jobRuns uint32
+	s := New(ctx, "test_schedule", "test_instance", 1*time.Second, NopLocker{}, ms,
+		WithJob("test_job", func(ctx context.Context) error {
+			atomic.AddUint32(&jobRuns, 1)
+			return nil
+		}))
+	s.Start()
+
+	// the scheduled runs are skipped
+	time.Sleep(2500 * time.Millisecond)
+	require.Zero(t, atomic.LoadUint32(&jobRuns))
+
+	// triggered runs still run
+	_, err := s.Trigger()
+	require.NoError(t, err)
+	time.Sleep(500 * time.Millisecond)
+	require.EqualValues(t, 1, atomic.LoadUint32(&jobRuns))
+
+	// the scheduled runs restart once resumed
+	ms.SetPaused(false)
+	time.Sleep(1500 * time.Millisecond)
+	require.Greater(t, atomic.LoadUint32(&jobRuns), uint32(1))
+}
+
 func TestScheduleReleaseLock(t *testing.T) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
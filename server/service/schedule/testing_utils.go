@@ -30,10 +30,14 @@ func (NopStatsStore) InsertCronStats(ctx context.Context, statsType fleet.CronSt
 	return 0, nil
 }
 
-func (NopStatsStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus) error {
+func (NopStatsStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error {
 	return nil
 }
 
+func (NopStatsStore) IsCronSchedulePaused(ctx context.Context, name string) (bool, error) {
+	return false, nil
+}
+
 func SetupMockLocker(name string, owner string, expiresAt time.Time) *MockLock {
 	return &MockLock{name: name, owner: owner, expiresAt: expiresAt}
 }
@@ -127,7 +131,8 @@ func (ml *MockLock) AddChannels(t *testing.T, chanNames ...string) error {
 
 type MockStatsStore struct {
 	sync.Mutex
-	stats map[int]fleet.CronStats
+	stats  map[int]fleet.CronStats
+	paused bool
 
 	GetStatsCalled    chan struct{}
 	InsertStatsCalled chan struct{}
@@ -179,7 +184,7 @@ func (m *MockStatsStore) InsertCronStats(ctx context.Context, statsType fleet.Cr
 	return id, nil
 }
 
-func (m *MockStatsStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus) error {
+func (m *MockStatsStore) UpdateCronStats(ctx context.Context, id int, status fleet.CronStatsStatus, cronErrors fleet.CronScheduleErrors) error {
 	m.Lock()
 	defer m.Unlock()
 
@@ -192,12 +197,28 @@ func (m *MockStatsStore) UpdateCronStats(ctx context.Context, id int, status fle
 		return errors.New("update failed, id not found")
 	}
 	s.Status = status
+	s.Errors = cronErrors
 	s.UpdatedAt = time.Now().Truncate(1 * time.Second)
 	m.stats[id] = s
 
 	return nil
 }
 
+func (m *MockStatsStore) IsCronSchedulePaused(ctx context.Context, name string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.paused, nil
+}
+
+// SetPaused pauses or resumes the scheduled runs.
+func (m *MockStatsStore) SetPaused(paused bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.paused = paused
+}
+
 func (m *MockStatsStore) AddChannels(t *testing.T, chanNames ...string) error {
 	m.Lock()
 	defer m.Unlock()
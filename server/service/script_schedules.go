@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create a script schedule
+////////////////////////////////////////////////////////////////////////////////
+
+type createScriptScheduleRequest struct {
+	ScriptID  uint   `json:"script_id"`
+	LocalTime string `json:"local_time"`
+}
+
+type createScriptScheduleResponse struct {
+	ScriptSchedule *fleet.ScriptSchedule `json:"script_schedule,omitempty"`
+	Err            error                 `json:"error,omitempty"`
+}
+
+func (r createScriptScheduleResponse) error() error { return r.Err }
+
+func createScriptScheduleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*createScriptScheduleRequest)
+	schedule, err := svc.NewScriptSchedule(ctx, req.ScriptID, req.LocalTime)
+	if err != nil {
+		return createScriptScheduleResponse{Err: err}, nil
+	}
+	return createScriptScheduleResponse{ScriptSchedule: schedule}, nil
+}
+
+func (svc *Service) NewScriptSchedule(ctx context.Context, scriptID uint, localTime string) (*fleet.ScriptSchedule, error) {
+	script, err := svc.authorizeScriptByID(ctx, scriptID, fleet.ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := fleet.ParseScheduleLocalTime(localTime); err != nil {
+		return nil, fleet.NewInvalidArgumentError("local_time", `The local time must be a time of day in the "HH:MM" format (e.g. "02:00").`)
+	}
+
+	schedule := &fleet.ScriptSchedule{
+		ScriptID:  script.ID,
+		LocalTime: localTime,
+	}
+	if user := authz.UserFromContext(ctx); user != nil {
+		schedule.AuthorID = &user.ID
+	}
+	schedule, err = svc.ds.NewScriptSchedule(ctx, schedule)
+	if err != nil {
+		var existsErr fleet.AlreadyExistsError
+		if errors.As(err, &existsErr) {
+			err = fleet.NewInvalidArgumentError("local_time", "The script is already scheduled at this time.").WithStatus(http.StatusConflict)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "create script schedule")
+	}
+
+	teamName, err := svc.scriptTeamName(ctx, script.TeamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team name for create script schedule activity")
+	}
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeAddedScriptSchedule{
+			ScriptName: script.Name,
+			LocalTime:  schedule.LocalTime,
+			TeamID:     script.TeamID,
+			TeamName:   teamName,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new activity for create script schedule")
+	}
+
+	return schedule, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List script schedules
+////////////////////////////////////////////////////////////////////////////////
+
+type listScriptSchedulesRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type listScriptSchedulesResponse struct {
+	ScriptSchedules []*fleet.ScriptSchedule `json:"script_schedules"`
+	Err             error                   `json:"error,omitempty"`
+}
+
+func (r listScriptSchedulesResponse) error() error { return r.Err }
+
+func listScriptSchedulesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*listScriptSchedulesRequest)
+	schedules, err := svc.ListScriptSchedules(ctx, req.TeamID)
+	if err != nil {
+		return listScriptSchedulesResponse{Err: err}, nil
+	}
+	return listScriptSchedulesResponse{ScriptSchedules: schedules}, nil
+}
+
+func (svc *Service) ListScriptSchedules(ctx context.Context, teamID *uint) ([]*fleet.ScriptSchedule, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Script{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListScriptSchedules(ctx, teamID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete a script schedule
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteScriptScheduleRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteScriptScheduleResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteScriptScheduleResponse) error() error { return r.Err }
+func (r deleteScriptScheduleResponse) Status() int  { return http.StatusNoContent }
+
+func deleteScriptScheduleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteScriptScheduleRequest)
+	if err := svc.DeleteScriptSchedule(ctx, req.ID); err != nil {
+		return deleteScriptScheduleResponse{Err: err}, nil
+	}
+	return deleteScriptScheduleResponse{}, nil
+}
+
+func (svc *Service) DeleteScriptSchedule(ctx context.Context, id uint) error {
+	schedule, err := svc.ds.ScriptSchedule(ctx, id)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			// authorize with a no-team script as a fallback to not leak the
+			// existing/non existing ids, see authorizeScriptByID.
+			if err := svc.authz.Authorize(ctx, &fleet.Script{}, fleet.ActionWrite); err != nil {
+				return err
+			}
+		}
+		svc.authz.SkipAuthorization(ctx)
+		return ctxerr.Wrap(ctx, err, "get script schedule")
+	}
+
+	if err := svc.authz.Authorize(ctx, &fleet.Script{TeamID: schedule.TeamID}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if err := svc.ds.DeleteScriptSchedule(ctx, schedule.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete script schedule")
+	}
+
+	teamName, err := svc.scriptTeamName(ctx, schedule.TeamID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get team name for delete script schedule activity")
+	}
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeDeletedScriptSchedule{
+			ScriptName: schedule.ScriptName,
+			LocalTime:  schedule.LocalTime,
+			TeamID:     schedule.TeamID,
+			TeamName:   teamName,
+		},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "new activity for delete script schedule")
+	}
+	return nil
+}
+
+// scriptTeamName returns the name of the team of a script, nil for no team.
+func (svc *Service) scriptTeamName(ctx context.Context, teamID *uint) (*string, error) {
+	if teamID == nil || *teamID == 0 {
+		return nil, nil
+	}
+	tm, err := svc.EnterpriseOverrides.TeamByIDOrName(ctx, teamID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tm.Name, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Queue the scheduled scripts (cron job)
+////////////////////////////////////////////////////////////////////////////////
+
+// QueueScheduledScripts queues the scheduled scripts on the hosts for which
+// the local time of the schedule is due at now, in the timezone reported by
+// the host (or UTC if the host did not report it). A schedule queues its
+// script at most once per local day on a host.
+func QueueScheduledScripts(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, now time.Time) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if appConfig.ServerSettings.ScriptsDisabled {
+		return nil
+	}
+
+	hosts, err := ds.ListScriptScheduleHosts(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list script schedule hosts")
+	}
+
+	var queued int
+	for _, h := range hosts {
+		if h.ScriptContentID == 0 || !h.SupportsScript() {
+			continue
+		}
+		localDate, due := fleet.ScheduleDueDate(h.LocalTime, now, fleet.HostLocation(h.HostTimezone))
+		if !due || (h.LastRunDate != nil && *h.LastRunDate == localDate) {
+			continue
+		}
+
+		// do not pile up executions of the script on a host that did not run the
+		// previous one yet (e.g. because it is offline).
+		pending, err := ds.IsExecutionPendingForHost(ctx, h.HostID, h.ScriptID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "check pending script execution")
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		scriptID := h.ScriptID
+		if _, err := ds.NewScheduledHostScriptExecutionRequest(ctx, &fleet.HostScriptRequestPayload{
+			HostID:          h.HostID,
+			ScriptID:        &scriptID,
+			ScriptContentID: h.ScriptContentID,
+		}, h.ScriptScheduleID, localDate); err != nil {
+			return ctxerr.Wrap(ctx, err, "queue scheduled script")
+		}
+		queued++
+	}
+
+	level.Debug(logger).Log("msg", "queued scheduled scripts", "count", queued)
+	return nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptSchedulesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+
+	const (
+		team1ScriptID  = 1
+		noTeamScriptID = 2
+
+		team1ScheduleID  = 1
+		noTeamScheduleID = 2
+	)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.ScriptFunc = func(ctx context.Context, id uint) (*fleet.Script, error) {
+		switch id {
+		case team1ScriptID:
+			return &fleet.Script{ID: id, Name: "team.sh", TeamID: ptr.Uint(1)}, nil
+		default:
+			return &fleet.Script{ID: id, Name: "no-team.sh"}, nil
+		}
+	}
+	ds.NewScriptScheduleFunc = func(ctx context.Context, schedule *fleet.ScriptSchedule) (*fleet.ScriptSchedule, error) {
+		newSchedule := *schedule
+		newSchedule.ID = 1
+		return &newSchedule, nil
+	}
+	ds.ScriptScheduleFunc = func(ctx context.Context, id uint) (*fleet.ScriptSchedule, error) {
+		switch id {
+		case team1ScheduleID:
+			return &fleet.ScriptSchedule{ID: id, ScriptID: team1ScriptID, TeamID: ptr.Uint(1), LocalTime: "02:00"}, nil
+		default:
+			return &fleet.ScriptSchedule{ID: id, ScriptID: noTeamScriptID, LocalTime: "02:00"}, nil
+		}
+	}
+	ds.ListScriptSchedulesFunc = func(ctx context.Context, teamID *uint) ([]*fleet.ScriptSchedule, error) {
+		return nil, nil
+	}
+	ds.DeleteScriptScheduleFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.TeamFunc = func(ctx context.Context, id uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: id}, nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailTeamWrite   bool
+		shouldFailGlobalWrite bool
+		shouldFailTeamRead    bool
+		shouldFailGlobalRead  bool
+	}{
+		{
+			name:                  "global admin",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+			shouldFailTeamRead:    false,
+			shouldFailGlobalRead:  false,
+		},
+		{
+			name:                  "global maintainer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: false,
+			shouldFailTeamRead:    false,
+			shouldFailGlobalRead:  false,
+		},
+		{
+			name:                  "global observer",
+			user:                  &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+			shouldFailTeamRead:    false,
+			shouldFailGlobalRead:  false,
+		},
+		{
+			name:                  "team admin, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailTeamWrite:   false,
+			shouldFailGlobalWrite: true,
+			shouldFailTeamRead:    false,
+			shouldFailGlobalRead:  true,
+		},
+		{
+			name:                  "team observer, belongs to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+			shouldFailTeamRead:    false,
+			shouldFailGlobalRead:  true,
+		},
+		{
+			name:                  "team admin, DOES NOT belong to team",
+			user:                  &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailTeamWrite:   true,
+			shouldFailGlobalWrite: true,
+			shouldFailTeamRead:    true,
+			shouldFailGlobalRead:  true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx = viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewScriptSchedule(ctx, noTeamScriptID, "02:00")
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			err = svc.DeleteScriptSchedule(ctx, noTeamScheduleID)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.ListScriptSchedules(ctx, nil)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.NewScriptSchedule(ctx, team1ScriptID, "02:00")
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			err = svc.DeleteScriptSchedule(ctx, team1ScheduleID)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+			_, err = svc.ListScriptSchedules(ctx, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+
+	t.Run("invalid local time", func(t *testing.T) {
+		ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+		_, err := svc.NewScriptSchedule(ctx, noTeamScriptID, "2am")
+		require.Error(t, err)
+		require.ErrorContains(t, err, "local_time")
+	})
+}
+
+func TestQueueScheduledScripts(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	// 2024-05-02 06:10 UTC is 02:10 in New York and 15:10 in Tokyo
+	now := time.Date(2024, 5, 2, 6, 10, 0, 0, time.UTC)
+
+	var scriptsDisabled bool
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ScriptsDisabled: scriptsDisabled}}, nil
+	}
+	ds.ListScriptScheduleHostsFunc = func(ctx context.Context) ([]*fleet.ScriptScheduleHost, error) {
+		base := fleet.ScriptScheduleHost{ScriptScheduleID: 1, ScriptID: 1, ScriptName: "script.sh", ScriptContentID: 1, LocalTime: "02:00", HostPlatform: "darwin"}
+		due := base
+		due.HostID, due.HostTimezone = 1, ptr.String("America/New_York")
+		notDue := base
+		notDue.HostID, notDue.HostTimezone = 2, ptr.String("Asia/Tokyo")
+		alreadyRun := base
+		alreadyRun.HostID, alreadyRun.HostTimezone, alreadyRun.LastRunDate = 3, ptr.String("America/New_York"), ptr.String("2024-05-02")
+		ranYesterday := base
+		ranYesterday.HostID, ranYesterday.HostTimezone, ranYesterday.LastRunDate = 4, ptr.String("America/New_York"), ptr.String("2024-05-01")
+		pending := base
+		pending.HostID, pending.HostTimezone = 5, ptr.String("America/New_York")
+		unsupported := base
+		unsupported.HostID, unsupported.HostTimezone, unsupported.HostPlatform = 6, ptr.String("America/New_York"), "windows"
+		noTimezone := base
+		noTimezone.HostID = 7
+		return []*fleet.ScriptScheduleHost{&due, &notDue, &alreadyRun, &ranYesterday, &pending, &unsupported, &noTimezone}, nil
+	}
+	ds.IsExecutionPendingForHostFunc = func(ctx context.Context, hostID uint, scriptID uint) ([]*uint, error) {
+		if hostID == 5 {
+			return []*uint{ptr.Uint(1)}, nil
+		}
+		return nil, nil
+	}
+	queued := map[uint]string{}
+	ds.NewScheduledHostScriptExecutionRequestFunc = func(ctx context.Context, request *fleet.HostScriptRequestPayload, scheduleID uint, localDate string) (*fleet.HostScriptResult, error) {
+		require.EqualValues(t, 1, scheduleID)
+		require.NotNil(t, request.ScriptID)
+		require.EqualValues(t, 1, *request.ScriptID)
+		require.EqualValues(t, 1, request.ScriptContentID)
+		queued[request.HostID] = localDate
+		return &fleet.HostScriptResult{HostID: request.HostID}, nil
+	}
+
+	err := QueueScheduledScripts(ctx, ds, kitlog.NewNopLogger(), now)
+	require.NoError(t, err)
+	require.Equal(t, map[uint]string{1: "2024-05-02", 4: "2024-05-02"}, queued)
+
+	// without timezone, the host runs the schedule in UTC
+	queued = map[uint]string{}
+	err = QueueScheduledScripts(ctx, ds, kitlog.NewNopLogger(), time.Date(2024, 5, 2, 2, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, map[uint]string{7: "2024-05-02"}, queued)
+
+	// nothing is queued when scripts are disabled
+	scriptsDisabled = true
+	queued = map[uint]string{}
+	ds.ListScriptScheduleHostsFuncInvoked = false
+	err = QueueScheduledScripts(ctx, ds, kitlog.NewNopLogger(), now)
+	require.NoError(t, err)
+	require.Empty(t, queued)
+	require.False(t, ds.ListScriptScheduleHostsFuncInvoked)
+}
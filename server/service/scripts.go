@@ -235,7 +235,13 @@ func (svc *Service) RunHostScript(ctx context.Context, request *fleet.HostScript
 			hostTmID = *host.TeamID
 		}
 		if scriptTmID != hostTmID {
-			return nil, fleet.NewInvalidArgumentError("script_id", `The script does not belong to the same team (or no team) as the host.`)
+			shared, err := svc.sharedScriptAvailableToTeam(ctx, script, hostTmID)
+			if err != nil {
+				return nil, err
+			}
+			if !shared {
+				return nil, fleet.NewInvalidArgumentError("script_id", `The script does not belong to the same team (or no team) as the host.`)
+			}
 		}
 
 		r, err := svc.ds.IsExecutionPendingForHost(ctx, request.HostID, *request.ScriptID)
@@ -263,6 +269,9 @@ func (svc *Service) RunHostScript(ctx context.Context, request *fleet.HostScript
 	if err := fleet.ValidateHostScriptContents(request.ScriptContents, isSavedScript); err != nil {
 		return nil, fleet.NewInvalidArgumentError("script_contents", err.Error())
 	}
+	if err := validateSecretVariables(ctx, svc.ds, "script_contents", request.ScriptContents); err != nil {
+		return nil, err
+	}
 
 	asyncExecution := waitForResult <= 0
 
@@ -500,6 +509,9 @@ func (svc *Service) NewScript(ctx context.Context, teamID *uint, name string, r
 	if err := script.ValidateNewScript(); err != nil {
 		return nil, fleet.NewInvalidArgumentError("script", err.Error())
 	}
+	if err := validateSecretVariables(ctx, svc.ds, "script", script.ScriptContents); err != nil {
+		return nil, err
+	}
 
 	savedScript, err := svc.ds.NewScript(ctx, script)
 	if err != nil {
@@ -861,6 +873,15 @@ func (svc *Service) BatchSetScripts(ctx context.Context, maybeTmID *uint, maybeT
 		return nil
 	}
 
+	// secret variables are only checked when changes are saved, as a dry run
+	// does not create the secret variables that are applied along with the
+	// scripts.
+	for i, script := range scripts {
+		if err := validateSecretVariables(ctx, svc.ds, fmt.Sprintf("scripts[%d]", i), script.ScriptContents); err != nil {
+			return err
+		}
+	}
+
 	if err := svc.ds.BatchSetScripts(ctx, teamID, scripts); err != nil {
 		return ctxerr.Wrap(ctx, err, "batch saving scripts")
 	}
@@ -903,30 +924,38 @@ func (svc *Service) authorizeScriptByID(ctx context.Context, scriptID uint, auth
 ////////////////////////////////////////////////////////////////////////////////
 
 type lockHostRequest struct {
-	HostID uint `url:"id"`
+	HostID       uint   `json:"-" url:"id"`
+	Confirmation string `json:"confirmation"`
 }
 
 type lockHostResponse struct {
-	Err error `json:"error,omitempty"`
+	PendingApproval bool  `json:"pending_approval,omitempty"`
+	Err             error `json:"error,omitempty"`
 }
 
-func (r lockHostResponse) Status() int  { return http.StatusNoContent }
+func (r lockHostResponse) Status() int {
+	if r.PendingApproval {
+		return http.StatusAccepted
+	}
+	return http.StatusNoContent
+}
 func (r lockHostResponse) error() error { return r.Err }
 
 func lockHostEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*lockHostRequest)
-	if err := svc.LockHost(ctx, req.HostID); err != nil {
+	pendingApproval, err := svc.LockHost(ctx, req.HostID, req.Confirmation)
+	if err != nil {
 		return lockHostResponse{Err: err}, nil
 	}
-	return lockHostResponse{}, nil
+	return lockHostResponse{PendingApproval: pendingApproval}, nil
 }
 
-func (svc *Service) LockHost(ctx context.Context, hostID uint) error {
+func (svc *Service) LockHost(ctx context.Context, hostID uint, confirmation string) (bool, error) {
 	// skipauth: No authorization check needed due to implementation returning
 	// only license error.
 	svc.authz.SkipAuthorization(ctx)
 
-	return fleet.ErrMissingLicense
+	return false, fleet.ErrMissingLicense
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -978,30 +1007,133 @@ func (svc *Service) UnlockHost(ctx context.Context, hostID uint) (string, error)
 	return "", fleet.ErrMissingLicense
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Get host unlock PIN
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostUnlockPINRequest struct {
+	HostID uint `url:"id"`
+}
+
+type getHostUnlockPINResponse struct {
+	HostID    uint   `json:"host_id"`
+	UnlockPIN string `json:"unlock_pin"`
+	Err       error  `json:"error,omitempty"`
+}
+
+func (r getHostUnlockPINResponse) error() error { return r.Err }
+
+func getHostUnlockPINEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getHostUnlockPINRequest)
+	pin, err := svc.GetHostUnlockPIN(ctx, req.HostID)
+	if err != nil {
+		return getHostUnlockPINResponse{Err: err}, nil
+	}
+	return getHostUnlockPINResponse{HostID: req.HostID, UnlockPIN: pin}, nil
+}
+
+func (svc *Service) GetHostUnlockPIN(ctx context.Context, hostID uint) (string, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return "", fleet.ErrMissingLicense
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Wipe host
 ////////////////////////////////////////////////////////////////////////////////
 
 type wipeHostRequest struct {
-	HostID uint `url:"id"`
+	HostID       uint   `json:"-" url:"id"`
+	Confirmation string `json:"confirmation"`
 }
 
 type wipeHostResponse struct {
-	Err error `json:"error,omitempty"`
+	PendingApproval bool  `json:"pending_approval,omitempty"`
+	Err             error `json:"error,omitempty"`
 }
 
-func (r wipeHostResponse) Status() int  { return http.StatusNoContent }
+func (r wipeHostResponse) Status() int {
+	if r.PendingApproval {
+		return http.StatusAccepted
+	}
+	return http.StatusNoContent
+}
 func (r wipeHostResponse) error() error { return r.Err }
 
 func wipeHostEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
 	req := request.(*wipeHostRequest)
-	if err := svc.WipeHost(ctx, req.HostID); err != nil {
+	pendingApproval, err := svc.WipeHost(ctx, req.HostID, req.Confirmation)
+	if err != nil {
 		return wipeHostResponse{Err: err}, nil
 	}
-	return wipeHostResponse{}, nil
+	return wipeHostResponse{PendingApproval: pendingApproval}, nil
+}
+
+func (svc *Service) WipeHost(ctx context.Context, hostID uint, confirmation string) (bool, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return false, fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Isolate host
+////////////////////////////////////////////////////////////////////////////////
+
+type isolateHostRequest struct {
+	HostID uint `url:"id"`
+}
+
+type isolateHostResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r isolateHostResponse) Status() int  { return http.StatusNoContent }
+func (r isolateHostResponse) error() error { return r.Err }
+
+func isolateHostEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*isolateHostRequest)
+	if err := svc.IsolateHost(ctx, req.HostID); err != nil {
+		return isolateHostResponse{Err: err}, nil
+	}
+	return isolateHostResponse{}, nil
+}
+
+func (svc *Service) IsolateHost(ctx context.Context, hostID uint) error {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return fleet.ErrMissingLicense
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Unisolate host
+////////////////////////////////////////////////////////////////////////////////
+
+type unisolateHostRequest struct {
+	HostID uint `url:"id"`
+}
+
+type unisolateHostResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r unisolateHostResponse) Status() int  { return http.StatusNoContent }
+func (r unisolateHostResponse) error() error { return r.Err }
+
+func unisolateHostEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*unisolateHostRequest)
+	if err := svc.UnisolateHost(ctx, req.HostID); err != nil {
+		return unisolateHostResponse{Err: err}, nil
+	}
+	return unisolateHostResponse{}, nil
 }
 
-func (svc *Service) WipeHost(ctx context.Context, hostID uint) error {
+func (svc *Service) UnisolateHost(ctx context.Context, hostID uint) error {
 	// skipauth: No authorization check needed due to implementation returning
 	// only license error.
 	svc.authz.SkipAuthorization(ctx)
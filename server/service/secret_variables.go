@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"go.mozilla.org/pkcs7"
+	"howett.net/plist"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create secret variable
+////////////////////////////////////////////////////////////////////////////////
+
+type createSecretVariableRequest struct {
+	fleet.SecretVariablePayload
+}
+
+type createSecretVariableResponse struct {
+	SecretVariable *fleet.SecretVariable `json:"secret_variable,omitempty"`
+	Err            error                 `json:"error,omitempty"`
+}
+
+func (r createSecretVariableResponse) error() error { return r.Err }
+
+func createSecretVariableEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*createSecretVariableRequest)
+	secret, err := svc.CreateSecretVariable(ctx, req.SecretVariablePayload)
+	if err != nil {
+		return createSecretVariableResponse{Err: err}, nil
+	}
+	return createSecretVariableResponse{SecretVariable: secret}, nil
+}
+
+func (svc *Service) CreateSecretVariable(ctx context.Context, payload fleet.SecretVariablePayload) (*fleet.SecretVariable, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SecretVariable{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if err := svc.checkServerPrivateKey(ctx); err != nil {
+		return nil, err
+	}
+	if err := payload.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate secret variable")
+	}
+
+	secret, err := svc.ds.NewSecretVariable(ctx, payload)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create secret variable")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeCreatedSecretVariable{
+		SecretName: secret.Name,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for new secret variable")
+	}
+	return secret, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List secret variables
+////////////////////////////////////////////////////////////////////////////////
+
+type listSecretVariablesResponse struct {
+	SecretVariables []*fleet.SecretVariable `json:"secret_variables"`
+	Err             error                   `json:"error,omitempty"`
+}
+
+func (r listSecretVariablesResponse) error() error { return r.Err }
+
+func listSecretVariablesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	secrets, err := svc.ListSecretVariables(ctx)
+	if err != nil {
+		return listSecretVariablesResponse{Err: err}, nil
+	}
+	if secrets == nil {
+		secrets = []*fleet.SecretVariable{} // return empty json array instead of json null
+	}
+	return listSecretVariablesResponse{SecretVariables: secrets}, nil
+}
+
+func (svc *Service) ListSecretVariables(ctx context.Context) ([]*fleet.SecretVariable, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SecretVariable{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListSecretVariables(ctx)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete secret variable
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteSecretVariableRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteSecretVariableResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteSecretVariableResponse) error() error { return r.Err }
+
+func deleteSecretVariableEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteSecretVariableRequest)
+	if err := svc.DeleteSecretVariable(ctx, req.ID); err != nil {
+		return deleteSecretVariableResponse{Err: err}, nil
+	}
+	return deleteSecretVariableResponse{}, nil
+}
+
+func (svc *Service) DeleteSecretVariable(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.SecretVariable{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	secret, err := svc.ds.SecretVariable(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get secret variable")
+	}
+	if err := svc.ds.DeleteSecretVariable(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete secret variable")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDeletedSecretVariable{
+		SecretName: secret.Name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for deleted secret variable")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Apply secret variables spec
+////////////////////////////////////////////////////////////////////////////////
+
+type applySecretVariablesSpecRequest struct {
+	DryRun  bool                          `json:"-" query:"dry_run,optional"` // if true, apply validation but do not save changes
+	Secrets []fleet.SecretVariablePayload `json:"secrets"`
+}
+
+type applySecretVariablesSpecResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r applySecretVariablesSpecResponse) error() error { return r.Err }
+
+func applySecretVariablesSpecEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*applySecretVariablesSpecRequest)
+	if err := svc.ApplySecretVariablesSpec(ctx, req.Secrets, req.DryRun); err != nil {
+		return applySecretVariablesSpecResponse{Err: err}, nil
+	}
+	return applySecretVariablesSpecResponse{}, nil
+}
+
+func (svc *Service) ApplySecretVariablesSpec(ctx context.Context, secrets []fleet.SecretVariablePayload, dryRun bool) error {
+	if err := svc.authz.Authorize(ctx, &fleet.SecretVariable{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	if err := svc.checkServerPrivateKey(ctx); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(secrets))
+	seen := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		if err := secret.Validate(); err != nil {
+			return ctxerr.Wrap(ctx, err, "validate secret variable")
+		}
+		if seen[secret.Name] {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("secrets", "duplicate secret variable name: "+secret.Name))
+		}
+		seen[secret.Name] = true
+		names = append(names, secret.Name)
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := svc.ds.UpsertSecretVariables(ctx, secrets); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert secret variables")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedSecretVariables{
+		SecretNames: names,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for edited secret variables")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers for documents that reference secret variables
+////////////////////////////////////////////////////////////////////////////////
+
+func (svc *Service) checkServerPrivateKey(ctx context.Context) error {
+	if svc.config.Server.PrivateKey == "" {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't save secret variables. The server private key (server.private_key) is not configured.",
+		})
+	}
+	return nil
+}
+
+// validateSecretVariables checks that all secret variables referenced in the
+// documents exist. The argument name is used for the returned
+// InvalidArgumentError.
+func validateSecretVariables(ctx context.Context, ds fleet.Datastore, argName string, documents ...string) error {
+	var names []string
+	for _, doc := range documents {
+		names = append(names, fleet.ReferencedSecretVariables(doc)...)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	secrets, err := ds.GetSecretVariables(ctx, names)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get secret variables")
+	}
+	if missing := missingSecretVariables(names, secrets); len(missing) > 0 {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError(argName, "secret variables do not exist: "+strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// expandSecretVariables replaces the references to secret variables in the
+// document with their values. If escapeXML is true, the values are escaped to
+// be inserted in an XML document (e.g. a configuration profile).
+func expandSecretVariables(ctx context.Context, ds fleet.Datastore, document string, escapeXML bool) (string, error) {
+	names := fleet.ReferencedSecretVariables(document)
+	if len(names) == 0 {
+		return document, nil
+	}
+
+	secrets, err := ds.GetSecretVariables(ctx, names)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get secret variables")
+	}
+	if missing := missingSecretVariables(names, secrets); len(missing) > 0 {
+		return "", ctxerr.Errorf(ctx, "secret variables do not exist: %s", strings.Join(missing, ", "))
+	}
+
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		values[secret.Name] = secret.Value
+	}
+	var escape func(string) string
+	if escapeXML {
+		escape = func(s string) string {
+			var sb strings.Builder
+			_ = xml.EscapeText(&sb, []byte(s)) // cannot fail writing to a strings.Builder
+			return sb.String()
+		}
+	}
+	return fleet.ExpandSecretVariables(document, values, escape), nil
+}
+
+// redactSecretVariables masks the values of the secret variables referenced
+// in document from s, e.g. to remove them from the output of a script.
+func redactSecretVariables(ctx context.Context, ds fleet.Datastore, document, s string) (string, error) {
+	names := fleet.ReferencedSecretVariables(document)
+	if len(names) == 0 || s == "" {
+		return s, nil
+	}
+
+	secrets, err := ds.GetSecretVariables(ctx, names)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "get secret variables")
+	}
+	values := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		values = append(values, secret.Value)
+	}
+	return fleet.RedactSecretVariables(s, values), nil
+}
+
+// redactCommandResultsSecretVariables masks the values of all the secret
+// variables from the payloads of the command results. The references to the
+// secret variables are expanded before the commands are enqueued, so the
+// payloads do not tell which secret variables were used.
+func redactCommandResultsSecretVariables(ctx context.Context, ds fleet.Datastore, results []*fleet.MDMCommandResult) error {
+	var hasPayload bool
+	for _, res := range results {
+		if len(res.Payload) > 0 {
+			hasPayload = true
+			break
+		}
+	}
+	if !hasPayload {
+		return nil
+	}
+
+	list, err := ds.ListSecretVariables(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list secret variables")
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(list))
+	for _, secret := range list {
+		names = append(names, secret.Name)
+	}
+	secrets, err := ds.GetSecretVariables(ctx, names)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get secret variables")
+	}
+
+	// the values are XML-escaped when expanded in configuration profiles.
+	values := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		var sb strings.Builder
+		_ = xml.EscapeText(&sb, []byte(secret.Value)) // cannot fail writing to a strings.Builder
+		values = append(values, secret.Value, sb.String())
+	}
+	for _, res := range results {
+		if len(res.Payload) > 0 {
+			res.Payload = redactCommandPayloadSecretVariables(res.Payload, values)
+		}
+	}
+	return nil
+}
+
+// redactCommandPayloadSecretVariables masks the provided secret values from
+// the command payload. The Apple InstallProfile commands embed the signed
+// profile as base64-encoded data, if it contains any of the values it is
+// replaced by the redacted, unsigned, profile.
+func redactCommandPayloadSecretVariables(payload []byte, values []string) []byte {
+	var cmd map[string]any
+	if _, err := plist.Unmarshal(payload, &cmd); err == nil {
+		if command, ok := cmd["Command"].(map[string]any); ok && command["RequestType"] == "InstallProfile" {
+			if profile, ok := command["Payload"].([]byte); ok {
+				if p7, err := pkcs7.Parse(profile); err == nil {
+					profile = p7.Content
+				}
+				if redacted := fleet.RedactSecretVariables(string(profile), values); redacted != string(profile) {
+					command["Payload"] = []byte(redacted)
+					if b, err := plist.Marshal(cmd, plist.XMLFormat); err == nil {
+						return b
+					}
+				}
+			}
+		}
+	}
+	return []byte(fleet.RedactSecretVariables(string(payload), values))
+}
+
+func missingSecretVariables(names []string, secrets []*fleet.SecretVariable) []string {
+	found := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		found[secret.Name] = true
+	}
+	var missing []string
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !found[name] && !seen[name] {
+			seen[name] = true
+			missing = append(missing, fleet.SecretVariablePrefix+name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/micromdm/micromdm/pkg/crypto/profileutil"
+	"github.com/stretchr/testify/require"
+	"howett.net/plist"
+)
+
+func TestSecretVariablesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.NewSecretVariableFunc = func(ctx context.Context, secret fleet.SecretVariablePayload) (*fleet.SecretVariable, error) {
+		return &fleet.SecretVariable{ID: 1, Name: secret.Name}, nil
+	}
+	ds.ListSecretVariablesFunc = func(ctx context.Context) ([]*fleet.SecretVariable, error) {
+		return nil, nil
+	}
+	ds.SecretVariableFunc = func(ctx context.Context, id uint) (*fleet.SecretVariable, error) {
+		return &fleet.SecretVariable{ID: id, Name: "API_KEY"}, nil
+	}
+	ds.DeleteSecretVariableFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.UpsertSecretVariablesFunc = func(ctx context.Context, secrets []fleet.SecretVariablePayload) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailRead  bool
+		shouldFailWrite bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, true, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, true},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.CreateSecretVariable(ctx, fleet.SecretVariablePayload{Name: "API_KEY", Value: "abc"})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.ListSecretVariables(ctx)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			err = svc.DeleteSecretVariable(ctx, 1)
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			err = svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "API_KEY", Value: "abc"}}, false)
+			checkAuthErr(t, tt.shouldFailWrite, err)
+		})
+	}
+}
+
+func TestApplySecretVariablesSpec(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}})
+
+	ds.UpsertSecretVariablesFunc = func(ctx context.Context, secrets []fleet.SecretVariablePayload) error {
+		return nil
+	}
+	var activity fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, act fleet.ActivityDetails) error {
+		activity = act
+		return nil
+	}
+
+	// invalid name
+	err := svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "FLEET_SECRET_A", Value: "abc"}}, false)
+	require.ErrorContains(t, err, "must not include the FLEET_SECRET_ prefix")
+
+	// duplicate name
+	err = svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "A", Value: "abc"}, {Name: "A", Value: "def"}}, false)
+	require.ErrorContains(t, err, "duplicate secret variable name")
+	require.False(t, ds.UpsertSecretVariablesFuncInvoked)
+
+	// dry run does not save
+	err = svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "A", Value: "abc"}}, true)
+	require.NoError(t, err)
+	require.False(t, ds.UpsertSecretVariablesFuncInvoked)
+
+	err = svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "A", Value: "abc"}, {Name: "B", Value: "def"}}, false)
+	require.NoError(t, err)
+	require.True(t, ds.UpsertSecretVariablesFuncInvoked)
+	require.Equal(t, fleet.ActivityTypeEditedSecretVariables{SecretNames: []string{"A", "B"}}, activity)
+
+	// the server private key is required
+	cfg := config.TestConfig()
+	cfg.Server.PrivateKey = ""
+	svc, ctx = newTestServiceWithConfig(t, ds, cfg, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}})
+	err = svc.ApplySecretVariablesSpec(ctx, []fleet.SecretVariablePayload{{Name: "A", Value: "abc"}}, false)
+	require.ErrorContains(t, err, "private key")
+}
+
+func TestSecretVariablesHelpers(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	ds.GetSecretVariablesFunc = func(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
+		var secrets []*fleet.SecretVariable
+		for _, name := range names {
+			switch name {
+			case "TOKEN":
+				secrets = append(secrets, &fleet.SecretVariable{Name: name, Value: "s3cr<e>t"})
+			case "URL":
+				secrets = append(secrets, &fleet.SecretVariable{Name: name, Value: "https://example.com"})
+			}
+		}
+		return secrets, nil
+	}
+
+	// documents without references do not hit the datastore
+	require.NoError(t, validateSecretVariables(ctx, ds, "script", "echo hello"))
+	got, err := expandSecretVariables(ctx, ds, "echo hello", false)
+	require.NoError(t, err)
+	require.Equal(t, "echo hello", got)
+	require.False(t, ds.GetSecretVariablesFuncInvoked)
+
+	require.NoError(t, validateSecretVariables(ctx, ds, "script", "curl $FLEET_SECRET_URL", "echo ${FLEET_SECRET_TOKEN}"))
+	err = validateSecretVariables(ctx, ds, "script", "curl $FLEET_SECRET_URL $FLEET_SECRET_NOPE $FLEET_SECRET_NOPE")
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	require.ErrorContains(t, err, "secret variables do not exist: FLEET_SECRET_NOPE")
+
+	got, err = expandSecretVariables(ctx, ds, `curl -H "X: $FLEET_SECRET_TOKEN" $FLEET_SECRET_URL`, false)
+	require.NoError(t, err)
+	require.Equal(t, `curl -H "X: s3cr<e>t" https://example.com`, got)
+	got, err = expandSecretVariables(ctx, ds, `<string>$FLEET_SECRET_TOKEN</string>`, true)
+	require.NoError(t, err)
+	require.Equal(t, `<string>s3cr&lt;e&gt;t</string>`, got)
+	_, err = expandSecretVariables(ctx, ds, `$FLEET_SECRET_NOPE`, false)
+	require.ErrorContains(t, err, "FLEET_SECRET_NOPE")
+
+	got, err = redactSecretVariables(ctx, ds, `echo $FLEET_SECRET_TOKEN`, "token is s3cr<e>t")
+	require.NoError(t, err)
+	require.Equal(t, "token is "+fleet.MaskedPassword, got)
+}
+
+func TestReconcileWindowsProfilesMissingSecretVariable(t *testing.T) {
+	ds := new(mock.Store)
+	ctx := context.Background()
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
+	}
+	ds.ListMDMWindowsProfilesToInstallFunc = func(ctx context.Context) ([]*fleet.MDMWindowsProfilePayload, error) {
+		return []*fleet.MDMWindowsProfilePayload{
+			{ProfileUUID: "w1", ProfileName: "ok", HostUUID: "h1"},
+			{ProfileUUID: "w2", ProfileName: "secret", HostUUID: "h1"},
+			{ProfileUUID: "w2", ProfileName: "secret", HostUUID: "h2"},
+		}, nil
+	}
+	ds.ListMDMWindowsProfilesToRemoveFunc = func(ctx context.Context) ([]*fleet.MDMWindowsProfilePayload, error) {
+		return nil, nil
+	}
+	ds.GetMDMWindowsProfilesContentsFunc = func(ctx context.Context, profileUUIDs []string) (map[string][]byte, error) {
+		return map[string][]byte{
+			"w1": []byte(`<Replace><Item><Target><LocURI>./Device/Vendor/MSFT/A</LocURI></Target><Data>1</Data></Item></Replace>`),
+			"w2": []byte(`<Replace><Item><Target><LocURI>./Device/Vendor/MSFT/B</LocURI></Target><Data>$FLEET_SECRET_NOPE</Data></Item></Replace>`),
+		}, nil
+	}
+	ds.GetSecretVariablesFunc = func(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
+		return nil, nil
+	}
+	var enqueuedFor []string
+	ds.MDMWindowsInsertCommandForHostsFunc = func(ctx context.Context, hostUUIDs []string, cmd *fleet.MDMWindowsCommand) error {
+		enqueuedFor = append(enqueuedFor, hostUUIDs...)
+		return nil
+	}
+	ds.BulkDeleteMDMWindowsHostsConfigProfilesFunc = func(ctx context.Context, payload []*fleet.MDMWindowsProfilePayload) error {
+		return nil
+	}
+	var upserted []*fleet.MDMWindowsBulkUpsertHostProfilePayload
+	ds.BulkUpsertMDMWindowsHostProfilesFunc = func(ctx context.Context, payload []*fleet.MDMWindowsBulkUpsertHostProfilePayload) error {
+		upserted = payload
+		return nil
+	}
+
+	err := ReconcileWindowsProfiles(ctx, ds, kitlog.NewNopLogger())
+	require.NoError(t, err)
+
+	// only the profile without secret variables is enqueued
+	require.Equal(t, []string{"h1"}, enqueuedFor)
+	require.Len(t, upserted, 3)
+	for _, hp := range upserted {
+		require.NotNil(t, hp.Status, hp.ProfileUUID)
+		switch hp.ProfileUUID {
+		case "w1":
+			require.Equal(t, fleet.MDMDeliveryPending, *hp.Status)
+			require.NotEmpty(t, hp.CommandUUID)
+			require.Empty(t, hp.Detail)
+		case "w2":
+			require.Equal(t, fleet.MDMDeliveryFailed, *hp.Status)
+			require.Empty(t, hp.CommandUUID)
+			require.Contains(t, hp.Detail, "secret variables do not exist: FLEET_SECRET_NOPE")
+		}
+	}
+}
+
+func TestGetMDMCommandResultsRedactsSecretVariables(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}})
+
+	ds.GetMDMCommandPlatformFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "darwin", nil
+	}
+	ds.GetMDMAppleCommandRequestTypeFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "InstallProfile", nil
+	}
+	// the profile is signed and base64-encoded in the InstallProfile command
+	certPEM, keyPEM, err := generateCertWithAPNsTopic()
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	profile := `<string>token s3cr&lt;e&gt;t for https://example.com</string>`
+	signed, err := profileutil.Sign(cert.PrivateKey, leaf, []byte(profile))
+	require.NoError(t, err)
+	type installProfileCommand struct {
+		RequestType string
+		Payload     []byte
+	}
+	installCmd, err := plist.Marshal(map[string]any{
+		"CommandUUID": "uuid",
+		"Command":     installProfileCommand{RequestType: "InstallProfile", Payload: signed},
+	}, plist.XMLFormat)
+	require.NoError(t, err)
+	require.NotContains(t, string(installCmd), "s3cr")
+
+	ds.GetMDMAppleCommandResultsFunc = func(ctx context.Context, commandUUID string) ([]*fleet.MDMCommandResult, error) {
+		return []*fleet.MDMCommandResult{{
+			HostUUID:    "h1",
+			CommandUUID: commandUUID,
+			Payload:     installCmd,
+		}}, nil
+	}
+	redactedProfile := func(payload []byte) string {
+		var cmd struct {
+			CommandUUID string
+			Command     installProfileCommand
+		}
+		_, err := plist.Unmarshal(payload, &cmd)
+		require.NoError(t, err)
+		require.Equal(t, "uuid", cmd.CommandUUID)
+		require.Equal(t, "InstallProfile", cmd.Command.RequestType)
+		return string(cmd.Command.Payload)
+	}
+	ds.ListHostsLiteByUUIDsFunc = func(ctx context.Context, filter fleet.TeamFilter, uuids []string) ([]*fleet.Host, error) {
+		return []*fleet.Host{{UUID: "h1", Hostname: "host1"}}, nil
+	}
+	ds.ListSecretVariablesFunc = func(ctx context.Context) ([]*fleet.SecretVariable, error) {
+		return []*fleet.SecretVariable{{Name: "TOKEN"}, {Name: "URL"}}, nil
+	}
+	ds.GetSecretVariablesFunc = func(ctx context.Context, names []string) ([]*fleet.SecretVariable, error) {
+		require.ElementsMatch(t, []string{"TOKEN", "URL"}, names)
+		return []*fleet.SecretVariable{
+			{Name: "TOKEN", Value: "s3cr<e>t"},
+			{Name: "URL", Value: "https://example.com"},
+		}, nil
+	}
+
+	want := `<string>token ` + fleet.MaskedPassword + ` for ` + fleet.MaskedPassword + `</string>`
+	res, err := svc.GetMDMCommandResults(ctx, "uuid")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "host1", res[0].Hostname)
+	require.Equal(t, want, redactedProfile(res[0].Payload))
+
+	res, err = svc.GetMDMAppleCommandResults(ctx, "uuid")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, want, redactedProfile(res[0].Payload))
+
+	ds.GetMDMCommandPlatformFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "windows", nil
+	}
+	ds.GetMDMWindowsCommandResultsFunc = func(ctx context.Context, commandUUID string) ([]*fleet.MDMCommandResult, error) {
+		return []*fleet.MDMCommandResult{{
+			HostUUID:    "h1",
+			CommandUUID: commandUUID,
+			Payload:     []byte(`<Data>s3cr&lt;e&gt;t</Data>`),
+		}}, nil
+	}
+	res, err = svc.GetMDMCommandResults(ctx, "uuid")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, `<Data>`+fleet.MaskedPassword+`</Data>`, string(res[0].Payload))
+
+	// results without payloads do not load the secret variables
+	ds.GetMDMCommandPlatformFunc = func(ctx context.Context, commandUUID string) (string, error) {
+		return "darwin", nil
+	}
+	ds.ListSecretVariablesFuncInvoked = false
+	ds.GetMDMAppleCommandResultsFunc = func(ctx context.Context, commandUUID string) ([]*fleet.MDMCommandResult, error) {
+		return []*fleet.MDMCommandResult{{HostUUID: "h1", CommandUUID: commandUUID}}, nil
+	}
+	_, err = svc.GetMDMCommandResults(ctx, "uuid")
+	require.NoError(t, err)
+	require.False(t, ds.ListSecretVariablesFuncInvoked)
+}
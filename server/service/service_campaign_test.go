@@ -3,11 +3,13 @@ package service
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/live_query/live_query_mock"
 	"github.com/fleetdm/fleet/v4/server/mock"
+	mockresult "github.com/fleetdm/fleet/v4/server/mock/mockresult"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/pubsub"
 	ws "github.com/fleetdm/fleet/v4/server/websocket"
@@ -90,7 +93,7 @@ func TestStreamCampaignResultsClosesReditOnWSClose(t *testing.T) {
 		},
 	})
 	q := "select year, month, day, hour, minutes, seconds from time"
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, nil)
 	require.NoError(t, err)
 
 	pathHandler := makeStreamDistributedQueryCampaignResultsHandler(config.TestConfig().Server, svc, kitlog.NewNopLogger())
@@ -350,3 +353,296 @@ func TestCalculateOutputSize(t *testing.T) {
 		},
 	)
 }
+
+func TestStreamCampaignResultsV2(t *testing.T) {
+	ds := new(mock.Store)
+	lq := live_query_mock.New(t)
+	rs := &mockresult.QueryResultStore{}
+	svc, _ := newTestService(t, ds, rs, lq)
+
+	var mu sync.Mutex
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, UserID: 999, QueryID: 1, Status: fleet.QueryWaiting}
+	var activities int
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.SessionByKeyFunc = func(ctx context.Context, key string) (*fleet.Session, error) {
+		return &fleet.Session{
+			CreateTimestamp: fleet.CreateTimestamp{CreatedAt: time.Now()},
+			ID:              1,
+			AccessedAt:      time.Now(),
+			UserID:          999,
+			Key:             key,
+		}, nil
+	}
+	ds.MarkSessionAccessedFunc = func(context.Context, *fleet.Session) error {
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, GlobalRole: ptr.String(fleet.RoleAdmin)}, nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c := *campaign
+		return &c, nil
+	}
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		mu.Lock()
+		defer mu.Unlock()
+		campaign.Status = camp.Status
+		return nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &fleet.HostTargets{HostIDs: []uint{1, 2}}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 10, OnlineHosts: 10}, nil
+	}
+	ds.IsSavedQueryFunc = func(ctx context.Context, queryID uint) (bool, error) {
+		return false, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		mu.Lock()
+		defer mu.Unlock()
+		activities++
+		return nil
+	}
+
+	var readCursor *uint64
+	rs.ReadChannelFromCursorFunc = func(ctx context.Context, query fleet.DistributedQueryCampaign, cursor uint64) (<-chan interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		readCursor = &cursor
+		ch := make(chan interface{}, 2)
+		ch <- fleet.QueryResultsReplay{Cursor: cursor, Count: 1}
+		ch <- fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: query.ID,
+			Host:                       fleet.ResultHostData{ID: 2, Hostname: "foo"},
+			Rows:                       []map[string]string{{"a": "b"}},
+			Cursor:                     cursor + 1,
+		}
+		return ch, nil
+	}
+	var detached bool
+	rs.DetachCampaignFunc = func(ctx context.Context, campaignID uint) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		detached = true
+		return true, nil
+	}
+
+	pathHandler := makeStreamDistributedQueryCampaignResultsHandler(config.TestConfig().Server, svc, kitlog.NewNopLogger())
+	s := httptest.NewServer(pathHandler("/api/{fleetversion:(?:v1|latest)}/fleet/results/"))
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/latest/fleet/results/websocket"
+
+	connect := func(t *testing.T, data map[string]interface{}) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteJSON(ws.JSONMessage{Type: "auth", Data: map[string]interface{}{"token": "abc"}}))
+		require.NoError(t, conn.WriteJSON(ws.JSONMessage{Type: "select_campaign", Data: data}))
+		return conn
+	}
+	readUntil := func(t *testing.T, conn *websocket.Conn, typ string) json.RawMessage {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		for {
+			var msg struct {
+				Type string          `json:"type"`
+				Data json.RawMessage `json:"data"`
+			}
+			require.NoError(t, conn.ReadJSON(&msg))
+			if msg.Type == typ {
+				return msg.Data
+			}
+			require.NotEqual(t, "error", msg.Type, string(msg.Data))
+		}
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		conn := connect(t, map[string]interface{}{"campaign_id": campaign.ID, "version": 3})
+		defer conn.Close()
+		data := readUntil(t, conn, "error")
+		require.Contains(t, string(data), "unsupported version 3")
+	})
+
+	t.Run("resume and detach", func(t *testing.T) {
+		conn := connect(t, map[string]interface{}{"campaign_id": campaign.ID, "version": 2, "cursor": 5})
+
+		var replay fleet.QueryResultsReplay
+		require.NoError(t, json.Unmarshal(readUntil(t, conn, "replay"), &replay))
+		require.Equal(t, fleet.QueryResultsReplay{Cursor: 5, Count: 1}, replay)
+
+		var res fleet.DistributedQueryResult
+		require.NoError(t, json.Unmarshal(readUntil(t, conn, "result"), &res))
+		require.Equal(t, uint64(6), res.Cursor)
+		require.Equal(t, "foo", res.Rows[0]["host_hostname"])
+
+		require.NoError(t, conn.Close())
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return detached
+		}, 10*time.Second, 100*time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotNil(t, readCursor)
+		require.Equal(t, uint64(5), *readCursor)
+		// the campaign is still running and no activity was created
+		require.Equal(t, fleet.QueryRunning, campaign.Status)
+		require.Zero(t, activities)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		mu.Lock()
+		detached = false
+		mu.Unlock()
+		lq.On("StopQuery", "42").Return(nil)
+
+		conn := connect(t, map[string]interface{}{"campaign_id": campaign.ID, "version": 2, "cursor": 6})
+		defer conn.Close()
+		readUntil(t, conn, "result")
+		require.NoError(t, conn.WriteJSON(ws.JSONMessage{Type: "stop_campaign", Data: map[string]interface{}{}}))
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return campaign.Status == fleet.QueryComplete && activities == 1
+		}, 10*time.Second, 100*time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.False(t, detached)
+		lq.AssertExpectations(t)
+	})
+
+	t.Run("completed campaign cannot be resumed", func(t *testing.T) {
+		conn := connect(t, map[string]interface{}{"campaign_id": campaign.ID, "version": 2, "cursor": 7})
+		defer conn.Close()
+		data := readUntil(t, conn, "error")
+		require.Contains(t, string(data), "cannot be resumed")
+	})
+}
+
+func TestStreamCampaignResultsPostProcessing(t *testing.T) {
+	ds := new(mock.Store)
+	lq := live_query_mock.New(t)
+	rs := &mockresult.QueryResultStore{}
+	svc, _ := newTestService(t, ds, rs, lq)
+
+	campaign := &fleet.DistributedQueryCampaign{
+		ID:             42,
+		UserID:         999,
+		QueryID:        1,
+		PostProcessing: &fleet.QueryPostProcessing{Function: fleet.QueryPostProcessingGroupBy, Column: "name"},
+	}
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.SessionByKeyFunc = func(ctx context.Context, key string) (*fleet.Session, error) {
+		return &fleet.Session{
+			CreateTimestamp: fleet.CreateTimestamp{CreatedAt: time.Now()},
+			ID:              1,
+			AccessedAt:      time.Now(),
+			UserID:          999,
+			Key:             key,
+		}, nil
+	}
+	ds.MarkSessionAccessedFunc = func(context.Context, *fleet.Session) error {
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, GlobalRole: ptr.String(fleet.RoleAdmin)}, nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		c := *campaign
+		return &c, nil
+	}
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		return nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &fleet.HostTargets{HostIDs: []uint{1, 2, 3}}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 3, OnlineHosts: 3}, nil
+	}
+	ds.IsSavedQueryFunc = func(ctx context.Context, queryID uint) (bool, error) {
+		return false, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	lq.On("StopQuery", "42").Return(nil)
+
+	rs.ReadChannelFunc = func(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error) {
+		ch := make(chan interface{}, 3)
+		ch <- fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: query.ID,
+			Host:                       fleet.ResultHostData{ID: 1},
+			Rows:                       []map[string]string{{"name": "a"}, {"name": "b"}},
+		}
+		ch <- fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: query.ID,
+			Host:                       fleet.ResultHostData{ID: 2},
+			Rows:                       []map[string]string{{"name": "b"}},
+		}
+		ch <- fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: query.ID,
+			Host:                       fleet.ResultHostData{ID: 3},
+			Error:                      ptr.String("failed"),
+		}
+		return ch, nil
+	}
+
+	pathHandler := makeStreamDistributedQueryCampaignResultsHandler(config.TestConfig().Server, svc, kitlog.NewNopLogger())
+	s := httptest.NewServer(pathHandler("/api/{fleetversion:(?:v1|latest)}/fleet/results/"))
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/latest/fleet/results/websocket"
+
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteJSON(ws.JSONMessage{Type: "auth", Data: map[string]interface{}{"token": "abc"}}))
+	require.NoError(t, conn.WriteJSON(ws.JSONMessage{Type: "select_campaign", Data: map[string]interface{}{"campaign_id": campaign.ID}}))
+
+	// the raw results are not sent, only the aggregate followed by the
+	// finished status.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	var aggregate fleet.QueryResultsAggregate
+	for {
+		var msg struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, conn.ReadJSON(&msg))
+		require.NotEqual(t, "result", msg.Type)
+		require.NotEqual(t, "error", msg.Type, string(msg.Data))
+		if msg.Type == "aggregate" {
+			require.NoError(t, json.Unmarshal(msg.Data, &aggregate))
+		}
+		if msg.Type == "status" {
+			var status campaignStatus
+			require.NoError(t, json.Unmarshal(msg.Data, &status))
+			if status.Status == campaignStatusFinished {
+				break
+			}
+		}
+	}
+
+	require.Equal(t, fleet.QueryPostProcessingGroupBy, aggregate.Function)
+	require.Equal(t, "name", aggregate.Column)
+	require.Equal(t, uint(3), aggregate.HostsResponded)
+	require.Equal(t, uint(1), aggregate.HostsFailed)
+	require.Equal(t, uint(3), aggregate.Rows)
+	require.Equal(t, []fleet.QueryResultsGroup{{Value: "b", Count: 2}, {Value: "a", Count: 1}}, aggregate.Groups)
+}
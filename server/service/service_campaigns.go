@@ -47,10 +47,15 @@ type statsTracker struct {
 	stats             []statsToSave
 }
 
-func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint) {
+func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint, opts fleet.LiveQueryStreamOptions) {
 	logging.WithExtras(ctx, "campaign_id", campaignID)
 	logger := log.With(svc.logger, "campaignID", campaignID)
 
+	// With version 2 of the protocol, the client can resume reading the
+	// results of the campaign after a disconnection, and stops the campaign
+	// explicitly.
+	resumable := opts.Version >= fleet.LiveQueryStreamVersion2
+
 	// Explicitly set ObserverCanRun: true in this check because we check that the user trying to
 	// read results is the same user that initiated the query. This means the observer check already
 	// happened with the actual value for this query.
@@ -85,19 +90,47 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		return
 	}
 
+	if resumable && opts.Cursor > 0 && campaign.Status == fleet.QueryComplete {
+		conn.WriteJSONError(fmt.Sprintf("campaign %d is completed and cannot be resumed", campaignID)) //nolint:errcheck
+		return
+	}
+
+	// For campaigns with post-processing, an aggregate of the results is sent
+	// instead of the raw rows.
+	var aggregate *fleet.QueryResultsAggregate
+	if campaign.PostProcessing != nil {
+		aggregate = fleet.NewQueryResultsAggregate(*campaign.PostProcessing)
+	}
+
 	// Open the channel from which we will receive incoming query results
 	// (probably from the redis pubsub implementation)
-	readChan, cancelFunc, err := svc.GetCampaignReader(ctx, campaign)
+	var resumeFrom *uint64
+	if resumable {
+		resumeFrom = &opts.Cursor
+		if aggregate != nil {
+			// the aggregate is computed again from all the buffered results.
+			var fromStart uint64
+			resumeFrom = &fromStart
+		}
+	}
+	readChan, cancelFunc, err := svc.getCampaignReader(ctx, campaign, resumeFrom)
 	if err != nil {
 		conn.WriteJSONError("error getting campaign reader: " + err.Error()) //nolint:errcheck
 		return
 	}
 	defer cancelFunc()
 
+	// detached is set when the client disconnected from a resumable campaign
+	// and the campaign's results are kept for when it reconnects.
+	var detached bool
+
 	// Setting the status to completed stops the query from being sent to
 	// targets. If this fails, there is a background job that will clean up
 	// this campaign.
 	defer func() {
+		if detached {
+			return
+		}
 		// We do not want to use the outer `ctx` because we want to make sure
 		// to cleanup the campaign.
 		ctx := context.WithoutCancel(ctx)
@@ -109,6 +142,10 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 	status := campaignStatus{
 		Status: campaignStatusPending,
 	}
+	if resumable && aggregate == nil {
+		// the results up to the cursor were already received by the client.
+		status.ActualResults = uint(opts.Cursor)
+	}
 	lastStatus := status
 	lastTotals := targetTotals{}
 
@@ -134,6 +171,20 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		return
 	}
 
+	// the aggregate is sent at most once per status update, only if it changed.
+	var aggregateChanged bool
+	writeAggregate := func() error {
+		if aggregate == nil || !aggregateChanged {
+			return nil
+		}
+		aggregate.Summarize()
+		if err := conn.WriteJSONMessage("aggregate", aggregate); err != nil {
+			return ctxerr.Wrap(ctx, err, "write aggregate")
+		}
+		aggregateChanged = false
+		return nil
+	}
+
 	updateStatus := func() error {
 		metrics, err := svc.CountHostsInTargets(ctx, &campaign.QueryID, *targets)
 		if err != nil {
@@ -156,6 +207,10 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 			}
 		}
 
+		if err := writeAggregate(); err != nil {
+			return err
+		}
+
 		status.ExpectedResults = totals.Online
 		if status.ActualResults >= status.ExpectedResults {
 			status.Status = campaignStatusFinished
@@ -193,10 +248,54 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 	queryID := campaign.QueryID
 	ctxWithoutCancel := context.WithoutCancel(ctx)
 	defer func() {
+		if detached {
+			// the campaign is not over, stats are aggregated and the activity
+			// is created when it completes.
+			svc.updateStats(ctxWithoutCancel, queryID, logger, &perfStatsTracker, false)
+			return
+		}
 		svc.updateStats(ctxWithoutCancel, queryID, logger, &perfStatsTracker, true)
 		svc.addLiveQueryActivity(ctxWithoutCancel, lastTotals.Total, queryID, logger)
 	}()
 
+	// stopCh is closed when the client stops the campaign (version 2 only).
+	var stopCh chan struct{}
+	if resumable {
+		stopCh = make(chan struct{})
+		go func() {
+			for {
+				msg, err := conn.ReadJSONMessage()
+				if err != nil {
+					// the session was closed
+					return
+				}
+				if msg.Type == "stop_campaign" {
+					close(stopCh)
+					return
+				}
+			}
+		}()
+
+		defer func() {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			if status.Status == campaignStatusFinished {
+				return
+			}
+			// The client disconnected before the campaign finished, keep its
+			// results so that it can resume reading them.
+			ok, err := svc.resultStore.DetachCampaign(ctxWithoutCancel, campaign.ID)
+			if err != nil {
+				level.Error(logger).Log("msg", "detach campaign", "err", err)
+				return
+			}
+			detached = ok
+		}()
+	}
+
 	// Loop, pushing updates to results and expected totals
 	for {
 		// Update the expected hosts total (Should happen before
@@ -206,12 +305,29 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		case res := <-readChan:
 			// Receive a result and push it over the websocket
 			switch res := res.(type) {
+			case fleet.QueryResultsReplay:
+				// Describes the buffered results that are replayed to a
+				// resuming client before the new results.
+				if err := conn.WriteJSONMessage("replay", res); err != nil {
+					if ctxerr.Cause(err) == sockjs.ErrSessionNotOpen {
+						return
+					}
+					_ = level.Error(logger).Log("msg", "error writing to channel", "err", err)
+				}
 			case fleet.DistributedQueryResult:
 				// Calculate result size for performance stats
 				outputSize := calculateOutputSize(&perfStatsTracker, &res)
-				mapHostnameRows(&res)
-				err = conn.WriteJSONMessage("result", res)
-				if perfStatsTracker.saveStats && res.Stats != nil {
+				if aggregate != nil {
+					aggregate.Add(res)
+					aggregateChanged = true
+				} else {
+					mapHostnameRows(&res)
+					err = conn.WriteJSONMessage("result", res)
+				}
+				// the stats of the results up to the client's cursor were
+				// already saved by the previous session.
+				replayed := resumable && res.Cursor > 0 && res.Cursor <= opts.Cursor
+				if perfStatsTracker.saveStats && res.Stats != nil && !replayed {
 					perfStatsTracker.stats = append(
 						perfStatsTracker.stats, statsToSave{hostID: res.Host.ID, Stats: res.Stats, outputSize: outputSize},
 					)
@@ -235,6 +351,13 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 				}
 			}
 
+		case <-stopCh:
+			// the client stopped the campaign, it is completed on return.
+			if err := writeAggregate(); err != nil {
+				level.Error(logger).Log("msg", "error writing aggregate", "err", err)
+			}
+			return
+
 		case <-ticker.C:
 			if conn.GetSessionState() == sockjs.SessionClosed {
 				// return and stop sending the query if the session was closed
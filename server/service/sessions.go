@@ -19,6 +19,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/sso"
 	"github.com/go-kit/kit/log/level"
+	kithttp "github.com/go-kit/kit/transport/http"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -30,10 +31,24 @@ type getInfoAboutSessionRequest struct {
 }
 
 type getInfoAboutSessionResponse struct {
-	SessionID uint      `json:"session_id"`
-	UserID    uint      `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	Err       error     `json:"error,omitempty"`
+	SessionID  uint      `json:"session_id"`
+	UserID     uint      `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Err        error     `json:"error,omitempty"`
+}
+
+func newGetInfoAboutSessionResponse(session *fleet.Session) getInfoAboutSessionResponse {
+	return getInfoAboutSessionResponse{
+		SessionID:  session.ID,
+		UserID:     session.UserID,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.AccessedAt,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+	}
 }
 
 func (r getInfoAboutSessionResponse) error() error { return r.Err }
@@ -45,11 +60,7 @@ func getInfoAboutSessionEndpoint(ctx context.Context, request interface{}, svc f
 		return getInfoAboutSessionResponse{Err: err}, nil
 	}
 
-	return getInfoAboutSessionResponse{
-		SessionID: session.ID,
-		UserID:    session.UserID,
-		CreatedAt: session.CreatedAt,
-	}, nil
+	return newGetInfoAboutSessionResponse(session), nil
 }
 
 func (svc *Service) GetInfoAboutSession(ctx context.Context, id uint) (*fleet.Session, error) {
@@ -121,7 +132,13 @@ type loginResponse struct {
 	User           *fleet.User          `json:"user,omitempty"`
 	AvailableTeams []*fleet.TeamSummary `json:"available_teams"`
 	Token          string               `json:"token,omitempty"`
-	Err            error                `json:"error,omitempty"`
+	// MFARequired is set when the password was valid but the login must be
+	// completed with a second factor, using MFAToken.
+	MFARequired     bool              `json:"mfa_required,omitempty"`
+	MFAToken        string            `json:"mfa_token,omitempty"`
+	MFAMethods      []fleet.MFAMethod `json:"mfa_methods,omitempty"`
+	WebAuthnOptions any               `json:"webauthn_options,omitempty"`
+	Err             error             `json:"error,omitempty"`
 }
 
 func (r loginResponse) error() error { return r.Err }
@@ -132,8 +149,23 @@ func loginEndpoint(ctx context.Context, request interface{}, svc fleet.Service)
 
 	user, session, err := svc.Login(ctx, req.Email, req.Password)
 	if err != nil {
+		var mfaErr *fleet.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return loginResponse{
+				AvailableTeams:  []*fleet.TeamSummary{},
+				MFARequired:     true,
+				MFAToken:        mfaErr.Token,
+				MFAMethods:      mfaErr.Methods,
+				WebAuthnOptions: mfaErr.WebAuthnOptions,
+			}, nil
+		}
 		return loginResponse{Err: err}, nil
 	}
+	return newLoginResponse(ctx, svc, user, session), nil
+}
+
+// newLoginResponse returns the response of a successful login of the user.
+func newLoginResponse(ctx context.Context, svc fleet.Service, user *fleet.User, session *fleet.Session) loginResponse {
 	// Add viewer to context to allow access to service teams for list of available teams.
 	ctx = viewer.NewContext(ctx, viewer.Viewer{
 		User:    user,
@@ -144,10 +176,10 @@ func loginEndpoint(ctx context.Context, request interface{}, svc fleet.Service)
 		if errors.Is(err, fleet.ErrMissingLicense) {
 			availableTeams = []*fleet.TeamSummary{}
 		} else {
-			return loginResponse{Err: err}, nil
+			return loginResponse{Err: err}
 		}
 	}
-	return loginResponse{user, availableTeams, session.Key, nil}, nil
+	return loginResponse{User: user, AvailableTeams: availableTeams, Token: session.Key}
 }
 
 func (svc *Service) Login(ctx context.Context, email, password string) (*fleet.User, *fleet.Session, error) {
@@ -195,6 +227,17 @@ func (svc *Service) Login(ctx context.Context, email, password string) (*fleet.U
 		return nil, nil, fleet.NewAuthFailedError("password login disabled for sso users")
 	}
 
+	if user.MFAEnabled && !user.APIOnly {
+		// the password is valid, but the login is only complete once the user
+		// provides a second factor (see LoginMFA)
+		var mfaErr *fleet.MFARequiredError
+		mfaErr, err = svc.newMFALoginChallenge(ctx, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, mfaErr
+	}
+
 	session, err := svc.makeSession(ctx, user.ID)
 	if err != nil {
 		return nil, nil, fleet.NewAuthFailedError(err.Error())
@@ -578,6 +621,10 @@ func (svc *Service) SSOSettings(ctx context.Context) (*fleet.SessionSSOSettings,
 	return settings, nil
 }
 
+// maxSessionUserAgentLength is the maximum length of the user agent stored
+// with a session, it matches the size of the sessions.user_agent column.
+const maxSessionUserAgentLength = 512
+
 // makeSession creates a new session for the given user.
 func (svc *Service) makeSession(ctx context.Context, userID uint) (*fleet.Session, error) {
 	sessionKeySize := svc.config.Session.KeySize
@@ -586,7 +633,11 @@ func (svc *Service) makeSession(ctx context.Context, userID uint) (*fleet.Sessio
 	if err != nil {
 		return nil, err
 	}
-	session, err := svc.ds.NewSession(ctx, userID, base64.StdEncoding.EncodeToString(key))
+	userAgent, _ := ctx.Value(kithttp.ContextKeyRequestUserAgent).(string)
+	if len(userAgent) > maxSessionUserAgentLength {
+		userAgent = userAgent[:maxSessionUserAgentLength]
+	}
+	session, err := svc.ds.NewSession(ctx, userID, base64.StdEncoding.EncodeToString(key), publicip.FromContext(ctx), userAgent)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "creating new session")
 	}
@@ -612,13 +663,26 @@ func (svc *Service) validateSession(ctx context.Context, session *fleet.Session)
 		return fleet.NewAuthRequiredError("active session not present")
 	}
 
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+
 	sessionDuration := svc.config.Session.Duration
+	if appConfig.SessionSettings.IdleTimeout > 0 {
+		sessionDuration = time.Duration(appConfig.SessionSettings.IdleTimeout) * time.Minute
+	}
+	maxLifetime := time.Duration(appConfig.SessionSettings.MaxSessionLifetime) * time.Minute
 	if session.APIOnly != nil && *session.APIOnly {
-		sessionDuration = 0 // make API-only tokens unlimited
+		// make API-only tokens unlimited
+		sessionDuration = 0
+		maxLifetime = 0
 	}
 
 	// duration 0 = unlimited
-	if sessionDuration != 0 && time.Since(session.AccessedAt) >= sessionDuration {
+	idleExpired := sessionDuration != 0 && time.Since(session.AccessedAt) >= sessionDuration
+	lifetimeExpired := maxLifetime != 0 && time.Since(session.CreatedAt) >= maxLifetime
+	if idleExpired || lifetimeExpired {
 		err := svc.ds.DestroySession(ctx, session)
 		if err != nil {
 			return ctxerr.Wrap(ctx, err, "destroying session")
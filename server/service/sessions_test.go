@@ -37,6 +37,9 @@ func TestSessionAuth(t *testing.T) {
 	ds.MarkSessionAccessedFunc = func(ctx context.Context, ssn *fleet.Session) error {
 		return nil
 	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
 
 	testCases := []struct {
 		name            string
@@ -150,24 +153,37 @@ func TestGetSessionByKey(t *testing.T) {
 	ds.MarkSessionAccessedFunc = func(ctx context.Context, ssn *fleet.Session) error {
 		return nil
 	}
+	var sessionSettings fleet.SessionSettings
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{SessionSettings: sessionSettings}, nil
+	}
 
 	cases := []struct {
 		desc     string
 		accessed time.Duration
+		created  time.Duration
 		apiOnly  bool
+		settings fleet.SessionSettings
 		fail     bool
 	}{
-		{"real user, accessed recently", -1 * time.Hour, false, false},
-		{"real user, accessed too long ago", -(cfg.Session.Duration + time.Hour), false, true},
-		{"api-only, accessed recently", -1 * time.Hour, true, false},
-		{"api-only, accessed long ago", -(cfg.Session.Duration + time.Hour), true, false},
+		{"real user, accessed recently", -1 * time.Hour, -1 * time.Hour, false, fleet.SessionSettings{}, false},
+		{"real user, accessed too long ago", -(cfg.Session.Duration + time.Hour), -(cfg.Session.Duration + time.Hour), false, fleet.SessionSettings{}, true},
+		{"api-only, accessed recently", -1 * time.Hour, -1 * time.Hour, true, fleet.SessionSettings{}, false},
+		{"api-only, accessed long ago", -(cfg.Session.Duration + time.Hour), -(cfg.Session.Duration + time.Hour), true, fleet.SessionSettings{}, false},
+		{"real user, idle timeout exceeded", -31 * time.Minute, -1 * time.Hour, false, fleet.SessionSettings{IdleTimeout: 30}, true},
+		{"real user, idle timeout not exceeded", -29 * time.Minute, -1 * time.Hour, false, fleet.SessionSettings{IdleTimeout: 30}, false},
+		{"real user, max lifetime exceeded", -1 * time.Minute, -25 * time.Hour, false, fleet.SessionSettings{MaxSessionLifetime: 24 * 60}, true},
+		{"real user, max lifetime not exceeded", -1 * time.Minute, -23 * time.Hour, false, fleet.SessionSettings{MaxSessionLifetime: 24 * 60}, false},
+		{"api-only, max lifetime exceeded", -1 * time.Minute, -25 * time.Hour, true, fleet.SessionSettings{MaxSessionLifetime: 24 * 60, IdleTimeout: 1}, false},
 	}
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
 			var authErr *fleet.AuthRequiredError
 			ds.SessionByKeyFuncInvoked, ds.DestroySessionFuncInvoked, ds.MarkSessionAccessedFuncInvoked = false, false, false
 
+			sessionSettings = tc.settings
 			theSession.AccessedAt = time.Now().Add(tc.accessed)
+			theSession.CreatedAt = time.Now().Add(tc.created)
 			theSession.APIOnly = ptr.Bool(tc.apiOnly)
 			_, err := svc.GetSessionByKey(ctx, theSession.Key)
 			if tc.fail {
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PATCH /scripts/{script_id}
+////////////////////////////////////////////////////////////////////////////////
+
+type updateScriptSharingRequest struct {
+	ScriptID uint `url:"script_id"`
+	fleet.SharedLibraryItemUpdate
+}
+
+type updateScriptSharingResponse struct {
+	*fleet.Script
+	Err error `json:"error,omitempty"`
+}
+
+func (r updateScriptSharingResponse) error() error { return r.Err }
+
+func updateScriptSharingEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*updateScriptSharingRequest)
+	script, err := svc.UpdateScriptSharing(ctx, req.ScriptID, req.SharedLibraryItemUpdate)
+	if err != nil {
+		return updateScriptSharingResponse{Err: err}, nil
+	}
+	return updateScriptSharingResponse{Script: script}, nil
+}
+
+func (svc *Service) UpdateScriptSharing(ctx context.Context, scriptID uint, payload fleet.SharedLibraryItemUpdate) (*fleet.Script, error) {
+	if err := svc.authorizeSharedLibraryWrite(ctx, nil); err != nil {
+		return nil, err
+	}
+	if payload.SharedWithAllTeams == nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("shared_with_all_teams", "The shared_with_all_teams field is required."))
+	}
+
+	script, err := svc.ds.Script(ctx, scriptID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get script")
+	}
+	if err := svc.ds.SetScriptSharedWithAllTeams(ctx, scriptID, *payload.SharedWithAllTeams); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set script shared with all teams")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedSharedLibraryItem{
+		ScriptID:           &script.ID,
+		Name:               script.Name,
+		SharedWithAllTeams: *payload.SharedWithAllTeams,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for script sharing")
+	}
+
+	return svc.ds.Script(ctx, scriptID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PATCH /configuration_profiles/{profile_uuid}
+////////////////////////////////////////////////////////////////////////////////
+
+type updateMDMConfigProfileSharingRequest struct {
+	ProfileUUID string `url:"profile_uuid"`
+	fleet.SharedLibraryItemUpdate
+}
+
+type updateMDMConfigProfileSharingResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r updateMDMConfigProfileSharingResponse) error() error { return r.Err }
+
+func updateMDMConfigProfileSharingEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*updateMDMConfigProfileSharingRequest)
+	if err := svc.UpdateMDMConfigProfileSharing(ctx, req.ProfileUUID, req.SharedLibraryItemUpdate); err != nil {
+		return updateMDMConfigProfileSharingResponse{Err: err}, nil
+	}
+	return updateMDMConfigProfileSharingResponse{}, nil
+}
+
+func (svc *Service) UpdateMDMConfigProfileSharing(ctx context.Context, profileUUID string, payload fleet.SharedLibraryItemUpdate) error {
+	if err := svc.authorizeSharedLibraryWrite(ctx, nil); err != nil {
+		return err
+	}
+	if payload.SharedWithAllTeams == nil {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("shared_with_all_teams", "The shared_with_all_teams field is required."))
+	}
+
+	_, name, err := svc.getMDMProfileTeamAndName(ctx, profileUUID)
+	if err != nil {
+		return err
+	}
+	if err := svc.ds.SetMDMConfigProfileSharedWithAllTeams(ctx, profileUUID, *payload.SharedWithAllTeams); err != nil {
+		return ctxerr.Wrap(ctx, err, "set profile shared with all teams")
+	}
+
+	if *payload.SharedWithAllTeams {
+		if err := svc.ds.BulkSetPendingMDMHostProfiles(ctx, nil, nil, []string{profileUUID}, nil); err != nil {
+			return ctxerr.Wrap(ctx, err, "bulk set pending host profiles")
+		}
+	} else {
+		// the profile no longer targets the hosts of the teams, so it must be
+		// removed from them.
+		teams, err := svc.ds.TeamsSummary(ctx)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "list teams")
+		}
+		teamIDs := make([]uint, 0, len(teams))
+		for _, tm := range teams {
+			teamIDs = append(teamIDs, tm.ID)
+		}
+		if len(teamIDs) > 0 {
+			if err := svc.ds.BulkSetPendingMDMHostProfiles(ctx, nil, teamIDs, nil, nil); err != nil {
+				return ctxerr.Wrap(ctx, err, "bulk set pending host profiles")
+			}
+		}
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedSharedLibraryItem{
+		ProfileUUID:        &profileUUID,
+		Name:               name,
+		SharedWithAllTeams: *payload.SharedWithAllTeams,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for profile sharing")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GET /teams/{id}/shared_library/opt_outs
+////////////////////////////////////////////////////////////////////////////////
+
+type getTeamSharedLibraryOptOutsRequest struct {
+	TeamID uint `url:"id"`
+}
+
+type teamSharedLibraryOptOutsResponse struct {
+	*fleet.TeamSharedLibraryOptOuts
+	Err error `json:"error,omitempty"`
+}
+
+func (r teamSharedLibraryOptOutsResponse) error() error { return r.Err }
+
+func getTeamSharedLibraryOptOutsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getTeamSharedLibraryOptOutsRequest)
+	optOuts, err := svc.GetTeamSharedLibraryOptOuts(ctx, req.TeamID)
+	if err != nil {
+		return teamSharedLibraryOptOutsResponse{Err: err}, nil
+	}
+	return teamSharedLibraryOptOutsResponse{TeamSharedLibraryOptOuts: optOuts}, nil
+}
+
+func (svc *Service) GetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error) {
+	lic, _ := license.FromContext(ctx)
+	if !lic.IsPremium() {
+		svc.authz.SkipAuthorization(ctx)
+		return nil, fleet.ErrMissingLicense
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.SharedLibrary{TeamID: &teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if _, err := svc.ds.Team(ctx, teamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team")
+	}
+	return svc.ds.GetTeamSharedLibraryOptOuts(ctx, teamID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUT /teams/{id}/shared_library/opt_outs
+////////////////////////////////////////////////////////////////////////////////
+
+type setTeamSharedLibraryOptOutsRequest struct {
+	TeamID uint `url:"id"`
+	fleet.TeamSharedLibraryOptOuts
+}
+
+func setTeamSharedLibraryOptOutsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*setTeamSharedLibraryOptOutsRequest)
+	optOuts, err := svc.SetTeamSharedLibraryOptOuts(ctx, req.TeamID, req.TeamSharedLibraryOptOuts)
+	if err != nil {
+		return teamSharedLibraryOptOutsResponse{Err: err}, nil
+	}
+	return teamSharedLibraryOptOutsResponse{TeamSharedLibraryOptOuts: optOuts}, nil
+}
+
+func (svc *Service) SetTeamSharedLibraryOptOuts(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) (*fleet.TeamSharedLibraryOptOuts, error) {
+	if err := svc.authorizeSharedLibraryWrite(ctx, &teamID); err != nil {
+		return nil, err
+	}
+
+	team, err := svc.ds.Team(ctx, teamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team")
+	}
+	if err := svc.ds.SetTeamSharedLibraryOptOuts(ctx, teamID, optOuts); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set team shared library opt-outs")
+	}
+	if err := svc.ds.BulkSetPendingMDMHostProfiles(ctx, nil, []uint{teamID}, nil, nil); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "bulk set pending host profiles")
+	}
+
+	res, err := svc.ds.GetTeamSharedLibraryOptOuts(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedTeamSharedLibraryOptOuts{
+		TeamID:       team.ID,
+		TeamName:     team.Name,
+		ScriptIDs:    res.ScriptIDs,
+		ProfileUUIDs: res.ProfileUUIDs,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for team shared library opt-outs")
+	}
+	return res, nil
+}
+
+// authorizeSharedLibraryWrite checks that the shared library is available
+// with the license and that the user can share items with all teams (teamID
+// nil) or opt the team out of shared items.
+func (svc *Service) authorizeSharedLibraryWrite(ctx context.Context, teamID *uint) error {
+	lic, _ := license.FromContext(ctx)
+	if !lic.IsPremium() {
+		svc.authz.SkipAuthorization(ctx)
+		return fleet.ErrMissingLicense
+	}
+	return svc.authz.Authorize(ctx, &fleet.SharedLibrary{TeamID: teamID}, fleet.ActionWrite)
+}
+
+// sharedScriptAvailableToTeam returns true if the script is a "no team"
+// script shared with all teams that the team did not opt out of nor override
+// with a script of the same name.
+func (svc *Service) sharedScriptAvailableToTeam(ctx context.Context, script *fleet.Script, teamID uint) (bool, error) {
+	if !script.SharedWithAllTeams || script.TeamID != nil || teamID == 0 {
+		return false, nil
+	}
+	id, err := svc.ds.GetScriptIDByName(ctx, script.Name, ptr.Uint(teamID))
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ctxerr.Wrap(ctx, err, "get script of team by name")
+	}
+	return id == script.ID, nil
+}
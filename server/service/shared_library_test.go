@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedLibraryAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
+	}
+	ds.ScriptFunc = func(ctx context.Context, id uint) (*fleet.Script, error) {
+		return &fleet.Script{ID: id, Name: "script.sh"}, nil
+	}
+	ds.SetScriptSharedWithAllTeamsFunc = func(ctx context.Context, scriptID uint, shared bool) error {
+		return nil
+	}
+	ds.GetMDMWindowsConfigProfileFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMWindowsConfigProfile, error) {
+		return &fleet.MDMWindowsConfigProfile{ProfileUUID: profileUUID, Name: "prof"}, nil
+	}
+	ds.SetMDMConfigProfileSharedWithAllTeamsFunc = func(ctx context.Context, profileUUID string, shared bool) error {
+		return nil
+	}
+	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
+		return nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	ds.GetTeamSharedLibraryOptOutsFunc = func(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error) {
+		return &fleet.TeamSharedLibraryOptOuts{}, nil
+	}
+	ds.SetTeamSharedLibraryOptOutsFunc = func(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name             string
+		user             *fleet.User
+		shouldFailShare  bool
+		shouldFailOptOut bool
+	}{
+		{
+			name: "global admin",
+			user: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+		},
+		{
+			name:            "global maintainer",
+			user:            &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			shouldFailShare: true,
+		},
+		{
+			name:             "global observer",
+			user:             &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			shouldFailShare:  true,
+			shouldFailOptOut: true,
+		},
+		{
+			name:            "team admin, belongs to team",
+			user:            &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}},
+			shouldFailShare: true,
+		},
+		{
+			name:             "team observer, belongs to team",
+			user:             &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			shouldFailShare:  true,
+			shouldFailOptOut: true,
+		},
+		{
+			name:             "team admin, DOES NOT belong to team",
+			user:             &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			shouldFailShare:  true,
+			shouldFailOptOut: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			share := fleet.SharedLibraryItemUpdate{SharedWithAllTeams: ptr.Bool(true)}
+			_, err := svc.UpdateScriptSharing(ctx, 1, share)
+			checkAuthErr(t, tt.shouldFailShare, err)
+			err = svc.UpdateMDMConfigProfileSharing(ctx, fleet.MDMWindowsProfileUUIDPrefix+"1", share)
+			checkAuthErr(t, tt.shouldFailShare, err)
+
+			_, err = svc.GetTeamSharedLibraryOptOuts(ctx, 1)
+			checkAuthErr(t, tt.shouldFailOptOut, err)
+			_, err = svc.SetTeamSharedLibraryOptOuts(ctx, 1, fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{1}})
+			checkAuthErr(t, tt.shouldFailOptOut, err)
+		})
+	}
+}
+
+func TestSharedLibrary(t *testing.T) {
+	ds := new(mock.Store)
+	admin := viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}}
+
+	t.Run("requires premium", func(t *testing.T) {
+		svc, ctx := newTestService(t, ds, nil, nil)
+		ctx = viewer.NewContext(ctx, admin)
+		_, err := svc.UpdateScriptSharing(ctx, 1, fleet.SharedLibraryItemUpdate{SharedWithAllTeams: ptr.Bool(true)})
+		require.ErrorIs(t, err, fleet.ErrMissingLicense)
+		_, err = svc.SetTeamSharedLibraryOptOuts(ctx, 1, fleet.TeamSharedLibraryOptOuts{})
+		require.ErrorIs(t, err, fleet.ErrMissingLicense)
+	})
+
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = viewer.NewContext(ctx, admin)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{MDM: fleet.MDM{WindowsEnabledAndConfigured: true}}, nil
+	}
+	ds.GetMDMWindowsConfigProfileFunc = func(ctx context.Context, profileUUID string) (*fleet.MDMWindowsConfigProfile, error) {
+		return &fleet.MDMWindowsConfigProfile{ProfileUUID: profileUUID, Name: "prof"}, nil
+	}
+	ds.SetMDMConfigProfileSharedWithAllTeamsFunc = func(ctx context.Context, profileUUID string, shared bool) error {
+		return nil
+	}
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return []*fleet.TeamSummary{{ID: 1}, {ID: 2}}, nil
+	}
+	var pendingTeamIDs []uint
+	var pendingProfileUUIDs []string
+	ds.BulkSetPendingMDMHostProfilesFunc = func(ctx context.Context, hostIDs, teamIDs []uint, profileUUIDs, hostUUIDs []string) error {
+		pendingTeamIDs, pendingProfileUUIDs = teamIDs, profileUUIDs
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	// the field is required
+	err := svc.UpdateMDMConfigProfileSharing(ctx, "w1", fleet.SharedLibraryItemUpdate{})
+	require.ErrorContains(t, err, "shared_with_all_teams")
+
+	// sharing the profile delivers it to the hosts of the teams
+	err = svc.UpdateMDMConfigProfileSharing(ctx, "w1", fleet.SharedLibraryItemUpdate{SharedWithAllTeams: ptr.Bool(true)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"w1"}, pendingProfileUUIDs)
+	require.Nil(t, pendingTeamIDs)
+
+	// unsharing it removes it from the hosts of all the teams
+	err = svc.UpdateMDMConfigProfileSharing(ctx, "w1", fleet.SharedLibraryItemUpdate{SharedWithAllTeams: ptr.Bool(false)})
+	require.NoError(t, err)
+	require.Equal(t, []uint{1, 2}, pendingTeamIDs)
+	require.Nil(t, pendingProfileUUIDs)
+
+	require.Equal(t, []fleet.ActivityDetails{
+		fleet.ActivityTypeEditedSharedLibraryItem{ProfileUUID: ptr.String("w1"), Name: "prof", SharedWithAllTeams: true},
+		fleet.ActivityTypeEditedSharedLibraryItem{ProfileUUID: ptr.String("w1"), Name: "prof", SharedWithAllTeams: false},
+	}, activities)
+
+	// opting out of shared items recomputes the profiles of the team
+	activities = nil
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	var stored fleet.TeamSharedLibraryOptOuts
+	ds.SetTeamSharedLibraryOptOutsFunc = func(ctx context.Context, teamID uint, optOuts fleet.TeamSharedLibraryOptOuts) error {
+		stored = optOuts
+		return nil
+	}
+	ds.GetTeamSharedLibraryOptOutsFunc = func(ctx context.Context, teamID uint) (*fleet.TeamSharedLibraryOptOuts, error) {
+		return &stored, nil
+	}
+	res, err := svc.SetTeamSharedLibraryOptOuts(ctx, 1, fleet.TeamSharedLibraryOptOuts{ScriptIDs: []uint{3}, ProfileUUIDs: []string{"w1"}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{3}, res.ScriptIDs)
+	require.Equal(t, []uint{1}, pendingTeamIDs)
+	require.Equal(t, []fleet.ActivityDetails{
+		fleet.ActivityTypeEditedTeamSharedLibraryOptOuts{TeamID: 1, TeamName: "team", ScriptIDs: []uint{3}, ProfileUUIDs: []string{"w1"}},
+	}, activities)
+}
@@ -35,6 +35,9 @@ func TestTeamAuth(t *testing.T) {
 	ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 		return &fleet.Team{}, nil
 	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
 	ds.ListUsersFunc = func(ctx context.Context, opt fleet.UserListOptions) ([]*fleet.User, error) {
 		return nil, nil
 	}
@@ -193,6 +196,84 @@ func TestTeamAuth(t *testing.T) {
 	}
 }
 
+func TestTeamAuthTenant(t *testing.T) {
+	ds := new(mock.Store)
+	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.NewTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+		team.ID = 3
+		return team, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+		return team, nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+	ds.DeleteTeamFunc = func(ctx context.Context, tid uint) error {
+		return nil
+	}
+	ds.TeamEnrollSecretsFunc = func(ctx context.Context, teamID uint) ([]*fleet.EnrollSecret, error) {
+		return nil, nil
+	}
+	ds.ListUsersFunc = func(ctx context.Context, opt fleet.UserListOptions) ([]*fleet.User, error) {
+		return nil, nil
+	}
+	ds.ListHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error) {
+		return []*fleet.Host{}, nil
+	}
+	var tenantTeams []uint
+	ds.SetTeamsTenantFunc = func(ctx context.Context, tenantID *uint, teamIDs []uint) error {
+		require.NotNil(t, tenantID)
+		require.Equal(t, uint(1), *tenantID)
+		tenantTeams = append(tenantTeams, teamIDs...)
+		return nil
+	}
+
+	// a global admin of the tenant only has access to the teams of its tenant.
+	user := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1}}
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: user})
+
+	for _, tc := range []struct {
+		teamID     uint
+		shouldFail bool
+	}{
+		{1, false},
+		{2, true},
+	} {
+		_, err := svc.GetTeam(ctx, tc.teamID)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		_, err = svc.ModifyTeam(ctx, tc.teamID, fleet.TeamPayload{Name: ptr.String("othername")})
+		checkAuthErr(t, tc.shouldFail, err)
+
+		_, err = svc.ListTeamUsers(ctx, tc.teamID, fleet.ListOptions{})
+		checkAuthErr(t, tc.shouldFail, err)
+
+		_, err = svc.TeamEnrollSecrets(ctx, tc.teamID)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		err = svc.DeleteTeam(ctx, tc.teamID)
+		checkAuthErr(t, tc.shouldFail, err)
+	}
+
+	// the teams created by the users of a tenant belong to the tenant
+	team, err := svc.NewTeam(ctx, fleet.TeamPayload{Name: ptr.String("name")})
+	require.NoError(t, err)
+	require.Equal(t, []uint{team.ID}, tenantTeams)
+	require.True(t, ds.SetTeamsTenantFuncInvoked)
+}
+
 func TestApplyTeamSpecs(t *testing.T) {
 	ds := new(mock.Store)
 	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
@@ -274,6 +355,10 @@ func TestApplyTeamSpecs(t *testing.T) {
 					team.ID = 1
 					return team, nil
 				}
+				ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+					require.Equal(t, uint(1), *teamID)
+					return &fleet.ConfigVersion{}, nil
+				}
 
 				ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 					act := activity.(fleet.ActivityTypeAppliedSpecTeam)
@@ -355,6 +440,9 @@ func TestApplyTeamSpecs(t *testing.T) {
 				ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 					return &fleet.Team{ID: 123}, nil
 				}
+				ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+					return &fleet.ConfigVersion{}, nil
+				}
 
 				ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
 					act := activity.(fleet.ActivityTypeAppliedSpecTeam)
@@ -397,6 +485,9 @@ func TestApplyTeamSpecEnrollSecretForNewTeams(t *testing.T) {
 			require.NotEmpty(t, team.Secrets[0])
 			return &fleet.Team{ID: 1}, nil
 		}
+		ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+			return &fleet.ConfigVersion{}, nil
+		}
 
 		_, err := svc.ApplyTeamSpecs(ctx, []*fleet.TeamSpec{{Name: "Foo"}}, fleet.ApplySpecOptions{})
 		require.NoError(t, err)
@@ -442,3 +533,52 @@ func TestApplyTeamSpecsErrorInTeamByName(t *testing.T) {
 	require.True(t, ok)
 	require.True(t, az.Checked())
 }
+
+func TestTeamInheritedSettings(t *testing.T) {
+	ds := new(mock.Store)
+	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: license, SkipCreateTestUsers: true})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	appConfig := &fleet.AppConfig{RemoteQueries: fleet.RemoteQuerySettings{Enabled: true}}
+	appConfig.WebhookSettings.HostStatusWebhook = fleet.HostStatusWebhookSettings{
+		Enable:         true,
+		DestinationURL: "https://global.example/hosts",
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	stored := &fleet.Team{ID: 1, Name: "team1"}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		tm := *stored
+		return &tm, nil
+	}
+	ds.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+		tm := *team
+		stored = &tm
+		return team, nil
+	}
+	ds.NewConfigVersionFunc = func(ctx context.Context, teamID *uint, author *fleet.User) (*fleet.ConfigVersion, error) {
+		return &fleet.ConfigVersion{}, nil
+	}
+
+	_, err := svc.ModifyTeam(ctx, 1, fleet.TeamPayload{InheritedSettings: &[]string{"agent_options"}})
+	require.ErrorContains(t, err, `"agent_options" can't be inherited`)
+	require.False(t, ds.SaveTeamFuncInvoked)
+
+	tm, err := svc.ModifyTeam(ctx, 1, fleet.TeamPayload{InheritedSettings: &[]string{"webhook_settings.host_status_webhook"}})
+	require.NoError(t, err)
+	require.Equal(t, fleet.TeamSettingInherited, tm.SettingsSources["webhook_settings.host_status_webhook"])
+	require.Equal(t, fleet.TeamSettingOverridden, tm.SettingsSources["remote_queries"])
+	require.Equal(t, &appConfig.WebhookSettings.HostStatusWebhook, tm.Config.WebhookSettings.HostStatusWebhook)
+	require.False(t, tm.Config.RemoteQueries.Enabled)
+
+	// the global value is not copied to the team, it is resolved when read
+	require.Nil(t, stored.Config.WebhookSettings.HostStatusWebhook)
+	require.Equal(t, []string{"webhook_settings.host_status_webhook"}, stored.Config.InheritedSettings)
+
+	appConfig.WebhookSettings.HostStatusWebhook.DestinationURL = "https://global.example/new"
+	tm, err = svc.GetTeam(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "https://global.example/new", tm.Config.WebhookSettings.HostStatusWebhook.DestinationURL)
+}
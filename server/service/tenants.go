@@ -0,0 +1,493 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mdm/nanomdm/cryptoutil"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create tenant
+////////////////////////////////////////////////////////////////////////////////
+
+type createTenantRequest struct {
+	fleet.TenantPayload
+}
+
+type tenantResponse struct {
+	Tenant *fleet.Tenant `json:"tenant,omitempty"`
+	Err    error         `json:"error,omitempty"`
+}
+
+func (r tenantResponse) error() error { return r.Err }
+
+func createTenantEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*createTenantRequest)
+	tenant, err := svc.NewTenant(ctx, req.TenantPayload)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func (svc *Service) NewTenant(ctx context.Context, payload fleet.TenantPayload) (*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if svc.config.Server.PrivateKey == "" {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't create the tenant. The server private key (server.private_key) is not configured.",
+		})
+	}
+
+	var tenant fleet.Tenant
+	if payload.Name == nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("name", "missing required argument"))
+	}
+	if err := applyTenantPayload(&tenant, payload); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate tenant")
+	}
+
+	created, err := svc.ds.NewTenant(ctx, &tenant)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create tenant")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeCreatedTenant{
+		ID:   created.ID,
+		Name: created.Name,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for new tenant")
+	}
+	return created, nil
+}
+
+// applyTenantPayload validates the payload and applies the fields that are
+// set to the tenant.
+func applyTenantPayload(tenant *fleet.Tenant, payload fleet.TenantPayload) error {
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			return fleet.NewInvalidArgumentError("name", "may not be empty")
+		}
+		tenant.Name = name
+	}
+	if payload.Description != nil {
+		tenant.Description = *payload.Description
+	}
+	if payload.Config != nil {
+		if hes := payload.Config.HostExpirySettings; hes != nil && hes.HostExpiryEnabled && hes.HostExpiryWindow <= 0 {
+			return fleet.NewInvalidArgumentError("config.host_expiry_settings.host_expiry_window", "must be greater than 0")
+		}
+		tenant.Config = *payload.Config
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List tenants
+////////////////////////////////////////////////////////////////////////////////
+
+type listTenantsResponse struct {
+	Tenants []*fleet.Tenant `json:"tenants"`
+	Err     error           `json:"error,omitempty"`
+}
+
+func (r listTenantsResponse) error() error { return r.Err }
+
+func listTenantsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	tenants, err := svc.ListTenants(ctx)
+	if err != nil {
+		return listTenantsResponse{Err: err}, nil
+	}
+	if tenants == nil {
+		tenants = []*fleet.Tenant{} // return empty json array instead of json null
+	}
+	return listTenantsResponse{Tenants: tenants}, nil
+}
+
+func (svc *Service) ListTenants(ctx context.Context) ([]*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListTenants(ctx)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get tenant
+////////////////////////////////////////////////////////////////////////////////
+
+type getTenantRequest struct {
+	ID uint `url:"id"`
+}
+
+func getTenantEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getTenantRequest)
+	tenant, err := svc.GetTenant(ctx, req.ID)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func (svc *Service) GetTenant(ctx context.Context, id uint) (*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.Tenant(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify tenant
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyTenantRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.TenantPayload
+}
+
+func modifyTenantEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyTenantRequest)
+	tenant, err := svc.ModifyTenant(ctx, req.ID, req.TenantPayload)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func (svc *Service) ModifyTenant(ctx context.Context, id uint, payload fleet.TenantPayload) (*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	tenant, err := svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if err := applyTenantPayload(tenant, payload); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate tenant")
+	}
+
+	tenant, err = svc.ds.SaveTenant(ctx, tenant)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save tenant")
+	}
+	if err := svc.newEditedTenantActivity(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (svc *Service) newEditedTenantActivity(ctx context.Context, tenant *fleet.Tenant) error {
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeEditedTenant{
+		ID:   tenant.ID,
+		Name: tenant.Name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for edited tenant")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete tenant
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteTenantRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteTenantResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteTenantResponse) error() error { return r.Err }
+
+func deleteTenantEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteTenantRequest)
+	if err := svc.DeleteTenant(ctx, req.ID); err != nil {
+		return deleteTenantResponse{Err: err}, nil
+	}
+	return deleteTenantResponse{}, nil
+}
+
+func (svc *Service) DeleteTenant(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	tenant, err := svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if tenant.TeamCount > 0 || tenant.UserCount > 0 {
+		return ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Couldn't delete the tenant. Remove its teams and users first.",
+		})
+	}
+	if err := svc.ds.DeleteTenant(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete tenant")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDeletedTenant{
+		ID:   tenant.ID,
+		Name: tenant.Name,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for deleted tenant")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Add/delete tenant teams
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyTenantTeamsRequest struct {
+	ID      uint   `json:"-" url:"id"`
+	TeamIDs []uint `json:"team_ids"`
+}
+
+func addTenantTeamsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyTenantTeamsRequest)
+	tenant, err := svc.AddTenantTeams(ctx, req.ID, req.TeamIDs)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func deleteTenantTeamsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyTenantTeamsRequest)
+	tenant, err := svc.DeleteTenantTeams(ctx, req.ID, req.TeamIDs)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func (svc *Service) AddTenantTeams(ctx context.Context, id uint, teamIDs []uint) (*fleet.Tenant, error) {
+	return svc.setTenantTeams(ctx, id, teamIDs, true)
+}
+
+func (svc *Service) DeleteTenantTeams(ctx context.Context, id uint, teamIDs []uint) (*fleet.Tenant, error) {
+	return svc.setTenantTeams(ctx, id, teamIDs, false)
+}
+
+func (svc *Service) setTenantTeams(ctx context.Context, id uint, teamIDs []uint, add bool) (*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	tenant, err := svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if len(teamIDs) == 0 {
+		return tenant, nil
+	}
+
+	for _, teamID := range teamIDs {
+		team, err := svc.ds.Team(ctx, teamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+		if !add && (team.TenantID == nil || *team.TenantID != id) {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("team_ids", fmt.Sprintf("team %q is not a team of the tenant", team.Name)))
+		}
+	}
+
+	tenantID := &id
+	if !add {
+		tenantID = nil
+	}
+	if err := svc.ds.SetTeamsTenant(ctx, tenantID, teamIDs); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set teams tenant")
+	}
+
+	tenant, err = svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if err := svc.newEditedTenantActivity(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Add/delete tenant users
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyTenantUsersRequest struct {
+	ID      uint   `json:"-" url:"id"`
+	UserIDs []uint `json:"user_ids"`
+}
+
+func addTenantUsersEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyTenantUsersRequest)
+	tenant, err := svc.AddTenantUsers(ctx, req.ID, req.UserIDs)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func deleteTenantUsersEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*modifyTenantUsersRequest)
+	tenant, err := svc.DeleteTenantUsers(ctx, req.ID, req.UserIDs)
+	if err != nil {
+		return tenantResponse{Err: err}, nil
+	}
+	return tenantResponse{Tenant: tenant}, nil
+}
+
+func (svc *Service) AddTenantUsers(ctx context.Context, id uint, userIDs []uint) (*fleet.Tenant, error) {
+	return svc.setTenantUsers(ctx, id, userIDs, true)
+}
+
+func (svc *Service) DeleteTenantUsers(ctx context.Context, id uint, userIDs []uint) (*fleet.Tenant, error) {
+	return svc.setTenantUsers(ctx, id, userIDs, false)
+}
+
+func (svc *Service) setTenantUsers(ctx context.Context, id uint, userIDs []uint, add bool) (*fleet.Tenant, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	tenant, err := svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if len(userIDs) == 0 {
+		return tenant, nil
+	}
+
+	currentUser := authz.UserFromContext(ctx)
+	for _, userID := range userIDs {
+		user, err := svc.ds.UserByID(ctx, userID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get user")
+		}
+		if !add {
+			if user.TenantID == nil || *user.TenantID != id {
+				return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("user_ids", fmt.Sprintf("user %q is not a user of the tenant", user.Email)))
+			}
+			continue
+		}
+
+		if currentUser != nil && currentUser.ID == user.ID {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("user_ids", "you cannot move yourself to a tenant"))
+		}
+		// the users of a tenant only have access to the teams of the tenant,
+		// their roles on other teams would be silently ignored.
+		for _, ut := range user.Teams {
+			team, err := svc.ds.Team(ctx, ut.ID)
+			if err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "get user team")
+			}
+			if team.TenantID == nil || *team.TenantID != id {
+				return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("user_ids",
+					fmt.Sprintf("user %q has a role on team %q, which is not a team of the tenant", user.Email, team.Name)))
+			}
+		}
+	}
+
+	tenantID := &id
+	if !add {
+		tenantID = nil
+	}
+	if err := svc.ds.SetUsersTenant(ctx, tenantID, userIDs); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set users tenant")
+	}
+
+	tenant, err = svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get tenant")
+	}
+	if err := svc.newEditedTenantActivity(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Set tenant APNs certificate
+////////////////////////////////////////////////////////////////////////////////
+
+type setTenantAPNsCertificateRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.TenantAPNsPayload
+}
+
+type setTenantAPNsCertificateResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r setTenantAPNsCertificateResponse) error() error { return r.Err }
+
+func setTenantAPNsCertificateEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*setTenantAPNsCertificateRequest)
+	if err := svc.SetTenantAPNsCertificate(ctx, req.ID, req.TenantAPNsPayload); err != nil {
+		return setTenantAPNsCertificateResponse{Err: err}, nil
+	}
+	return setTenantAPNsCertificateResponse{}, nil
+}
+
+func (svc *Service) SetTenantAPNsCertificate(ctx context.Context, id uint, payload fleet.TenantAPNsPayload) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Tenant{ID: id}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	tenant, err := svc.ds.Tenant(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get tenant")
+	}
+
+	certPEM, keyPEM := []byte(payload.Certificate), []byte(payload.PrivateKey)
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("certificate", "invalid certificate or private key: "+err.Error()))
+	}
+	topic, err := cryptoutil.TopicFromPEMCert(certPEM)
+	if err != nil {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("certificate", "not an APNs certificate: "+err.Error()))
+	}
+
+	// the push certificate of a host is found by its topic, which must identify
+	// a single certificate.
+	if topic == svc.mdmPushCertTopic {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("certificate", "the APNs certificate is the one of the Fleet server"))
+	}
+	tenants, err := svc.ds.ListTenants(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list tenants")
+	}
+	for _, other := range tenants {
+		if other.ID == id {
+			continue
+		}
+		assets, err := svc.ds.GetTenantMDMAssets(ctx, other.ID, []fleet.TenantMDMAssetName{fleet.TenantMDMAssetAPNSCert})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get tenant APNs certificate")
+		}
+		otherCert, ok := assets[fleet.TenantMDMAssetAPNSCert]
+		if !ok {
+			continue
+		}
+		if otherTopic, err := cryptoutil.TopicFromPEMCert(otherCert); err == nil && otherTopic == topic {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("certificate", fmt.Sprintf("the APNs certificate is already used by tenant %q", other.Name)))
+		}
+	}
+
+	if err := svc.ds.SetTenantMDMAssets(ctx, id, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: certPEM},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: keyPEM},
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "set tenant APNs certificate")
+	}
+	return svc.newEditedTenantActivity(ctx, tenant)
+}
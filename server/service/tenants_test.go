@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.NewTenantFunc = func(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
+		tenant.ID = 1
+		return tenant, nil
+	}
+	ds.ListTenantsFunc = func(ctx context.Context) ([]*fleet.Tenant, error) {
+		return nil, nil
+	}
+	ds.TenantFunc = func(ctx context.Context, id uint) (*fleet.Tenant, error) {
+		return &fleet.Tenant{ID: id, Name: "acme"}, nil
+	}
+	ds.SaveTenantFunc = func(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
+		return tenant, nil
+	}
+	ds.DeleteTenantFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, TenantID: ptr.Uint(1)}, nil
+	}
+	ds.SetTeamsTenantFunc = func(ctx context.Context, tenantID *uint, teamIDs []uint) error {
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, TenantID: ptr.Uint(1)}, nil
+	}
+	ds.SetUsersTenantFunc = func(ctx context.Context, tenantID *uint, userIDs []uint) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"tenant global admin", &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1}}, true},
+		{"global maintainer", &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleMaintainer)}, true},
+		{"global gitops", &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleGitOps)}, true},
+		{"global observer", &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team admin", &fleet.User{ID: 100, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewTenant(ctx, fleet.TenantPayload{Name: ptr.String("acme")})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.ListTenants(ctx)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.GetTenant(ctx, 1)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.ModifyTenant(ctx, 1, fleet.TenantPayload{Description: ptr.String("desc")})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			err = svc.DeleteTenant(ctx, 1)
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.AddTenantTeams(ctx, 1, []uint{1})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.DeleteTenantTeams(ctx, 1, []uint{1})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.AddTenantUsers(ctx, 1, []uint{2})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			_, err = svc.DeleteTenantUsers(ctx, 1, []uint{2})
+			checkAuthErr(t, tt.shouldFail, err)
+
+			// the certificate is invalid, so only the authorization passes
+			err = svc.SetTenantAPNsCertificate(ctx, 1, fleet.TenantAPNsPayload{})
+			if tt.shouldFail {
+				checkAuthErr(t, true, err)
+			} else {
+				require.ErrorContains(t, err, "invalid certificate or private key")
+			}
+		})
+	}
+}
+
+func TestTenantsValidation(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	admin := &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: admin})
+
+	tenant := &fleet.Tenant{ID: 1, Name: "acme"}
+	ds.NewTenantFunc = func(ctx context.Context, tenant *fleet.Tenant) (*fleet.Tenant, error) {
+		tenant.ID = 1
+		return tenant, nil
+	}
+	ds.TenantFunc = func(ctx context.Context, id uint) (*fleet.Tenant, error) {
+		if id != tenant.ID {
+			return nil, &notFoundError{}
+		}
+		cp := *tenant
+		return &cp, nil
+	}
+	ds.SaveTenantFunc = func(ctx context.Context, t *fleet.Tenant) (*fleet.Tenant, error) {
+		tenant = t
+		return t, nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	t.Run("create and modify", func(t *testing.T) {
+		_, err := svc.NewTenant(ctx, fleet.TenantPayload{})
+		require.ErrorContains(t, err, "name")
+		_, err = svc.NewTenant(ctx, fleet.TenantPayload{Name: ptr.String("  ")})
+		require.ErrorContains(t, err, "may not be empty")
+		_, err = svc.NewTenant(ctx, fleet.TenantPayload{Name: ptr.String("acme"), Config: &fleet.TenantConfig{
+			HostExpirySettings: &fleet.HostExpirySettings{HostExpiryEnabled: true},
+		}})
+		require.ErrorContains(t, err, "must be greater than 0")
+		require.False(t, ds.NewTenantFuncInvoked)
+
+		created, err := svc.NewTenant(ctx, fleet.TenantPayload{Name: ptr.String(" acme "), Description: ptr.String("desc")})
+		require.NoError(t, err)
+		require.Equal(t, "acme", created.Name)
+		require.Equal(t, "desc", created.Description)
+		require.Equal(t, fleet.ActivityTypeCreatedTenant{ID: 1, Name: "acme"}, activities[len(activities)-1])
+
+		// the fields that are not set are left unchanged
+		modified, err := svc.ModifyTenant(ctx, 1, fleet.TenantPayload{Config: &fleet.TenantConfig{
+			OrgInfo: &fleet.OrgInfo{OrgName: "Acme Inc."},
+		}})
+		require.NoError(t, err)
+		require.Equal(t, "acme", modified.Name)
+		require.Equal(t, "Acme Inc.", modified.Config.OrgInfo.OrgName)
+		require.Equal(t, fleet.ActivityTypeEditedTenant{ID: 1, Name: "acme"}, activities[len(activities)-1])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		tenant.TeamCount = 1
+		err := svc.DeleteTenant(ctx, 1)
+		require.ErrorContains(t, err, "Remove its teams and users first")
+		require.False(t, ds.DeleteTenantFuncInvoked)
+		tenant.TeamCount = 0
+	})
+
+	t.Run("teams", func(t *testing.T) {
+		ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+			switch tid {
+			case 1:
+				return &fleet.Team{ID: tid, Name: "in", TenantID: ptr.Uint(1)}, nil
+			default:
+				return &fleet.Team{ID: tid, Name: "out"}, nil
+			}
+		}
+		var setTenantID *uint
+		var setTeamIDs []uint
+		ds.SetTeamsTenantFunc = func(ctx context.Context, tenantID *uint, teamIDs []uint) error {
+			setTenantID, setTeamIDs = tenantID, teamIDs
+			return nil
+		}
+
+		_, err := svc.AddTenantTeams(ctx, 1, []uint{2})
+		require.NoError(t, err)
+		require.Equal(t, ptr.Uint(1), setTenantID)
+		require.Equal(t, []uint{2}, setTeamIDs)
+
+		_, err = svc.DeleteTenantTeams(ctx, 1, []uint{1, 2})
+		require.ErrorContains(t, err, `team "out" is not a team of the tenant`)
+		_, err = svc.DeleteTenantTeams(ctx, 1, []uint{1})
+		require.NoError(t, err)
+		require.Nil(t, setTenantID)
+		require.Equal(t, []uint{1}, setTeamIDs)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+			switch id {
+			case 1:
+				return &fleet.User{ID: id, Email: "in@example.com", TenantID: ptr.Uint(1)}, nil
+			case 2:
+				return &fleet.User{ID: id, Email: "team@example.com", Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, nil
+			case 3:
+				return &fleet.User{ID: id, Email: "other@example.com", Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, nil
+			default:
+				return admin, nil
+			}
+		}
+		var setTenantID *uint
+		var setUserIDs []uint
+		ds.SetUsersTenantFunc = func(ctx context.Context, tenantID *uint, userIDs []uint) error {
+			setTenantID, setUserIDs = tenantID, userIDs
+			return nil
+		}
+
+		_, err := svc.AddTenantUsers(ctx, 1, []uint{admin.ID})
+		require.ErrorContains(t, err, "you cannot move yourself to a tenant")
+		_, err = svc.AddTenantUsers(ctx, 1, []uint{2, 3})
+		require.ErrorContains(t, err, `user "other@example.com" has a role on team "out"`)
+		require.False(t, ds.SetUsersTenantFuncInvoked)
+
+		_, err = svc.AddTenantUsers(ctx, 1, []uint{2})
+		require.NoError(t, err)
+		require.Equal(t, ptr.Uint(1), setTenantID)
+		require.Equal(t, []uint{2}, setUserIDs)
+
+		_, err = svc.DeleteTenantUsers(ctx, 1, []uint{1, 3})
+		require.ErrorContains(t, err, `user "other@example.com" is not a user of the tenant`)
+		_, err = svc.DeleteTenantUsers(ctx, 1, []uint{1})
+		require.NoError(t, err)
+		require.Nil(t, setTenantID)
+		require.Equal(t, []uint{1}, setUserIDs)
+	})
+}
+
+func TestSetTenantAPNsCertificate(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{APNSTopic: "com.apple.mgmt.Global"})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 100, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	globalCertPEM, globalKeyPEM, err := generateCertWithTopic("com.apple.mgmt.Global")
+	require.NoError(t, err)
+	otherCertPEM, otherKeyPEM, err := generateCertWithTopic("com.apple.mgmt.Other")
+	require.NoError(t, err)
+	certPEM, keyPEM, err := generateCertWithTopic("com.apple.mgmt.Acme")
+	require.NoError(t, err)
+
+	ds.TenantFunc = func(ctx context.Context, id uint) (*fleet.Tenant, error) {
+		return &fleet.Tenant{ID: id, Name: "acme"}, nil
+	}
+	ds.ListTenantsFunc = func(ctx context.Context) ([]*fleet.Tenant, error) {
+		return []*fleet.Tenant{{ID: 1, Name: "acme"}, {ID: 2, Name: "other"}}, nil
+	}
+	ds.GetTenantMDMAssetsFunc = func(ctx context.Context, tenantID uint, names []fleet.TenantMDMAssetName) (map[fleet.TenantMDMAssetName][]byte, error) {
+		require.Equal(t, uint(2), tenantID)
+		return map[fleet.TenantMDMAssetName][]byte{fleet.TenantMDMAssetAPNSCert: otherCertPEM}, nil
+	}
+	var assets []fleet.TenantMDMAsset
+	ds.SetTenantMDMAssetsFunc = func(ctx context.Context, tenantID uint, a []fleet.TenantMDMAsset) error {
+		require.Equal(t, uint(1), tenantID)
+		assets = a
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	// the key must match the certificate
+	err = svc.SetTenantAPNsCertificate(ctx, 1, fleet.TenantAPNsPayload{Certificate: string(certPEM), PrivateKey: string(otherKeyPEM)})
+	require.ErrorContains(t, err, "invalid certificate or private key")
+
+	// the topic must identify a single certificate
+	err = svc.SetTenantAPNsCertificate(ctx, 1, fleet.TenantAPNsPayload{Certificate: string(globalCertPEM), PrivateKey: string(globalKeyPEM)})
+	require.ErrorContains(t, err, "the one of the Fleet server")
+	err = svc.SetTenantAPNsCertificate(ctx, 1, fleet.TenantAPNsPayload{Certificate: string(otherCertPEM), PrivateKey: string(otherKeyPEM)})
+	require.ErrorContains(t, err, `already used by tenant "other"`)
+	require.False(t, ds.SetTenantMDMAssetsFuncInvoked)
+
+	err = svc.SetTenantAPNsCertificate(ctx, 1, fleet.TenantAPNsPayload{Certificate: string(certPEM), PrivateKey: string(keyPEM)})
+	require.NoError(t, err)
+	require.Equal(t, []fleet.TenantMDMAsset{
+		{Name: fleet.TenantMDMAssetAPNSCert, Value: certPEM},
+		{Name: fleet.TenantMDMAssetAPNSKey, Value: keyPEM},
+	}, assets)
+}
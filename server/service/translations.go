@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List translations
+////////////////////////////////////////////////////////////////////////////////
+
+type listTranslationsRequest struct{}
+
+type listTranslationsResponse struct {
+	DefaultLocale   string                      `json:"default_locale"`
+	DefaultMessages map[fleet.MessageKey]string `json:"default_messages"`
+	Translations    []*fleet.Translations       `json:"translations"`
+	Err             error                       `json:"error,omitempty"`
+}
+
+func (r listTranslationsResponse) error() error { return r.Err }
+
+func listTranslationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	translations, err := svc.ListTranslations(ctx)
+	if err != nil {
+		return listTranslationsResponse{Err: err}, nil
+	}
+	return listTranslationsResponse{
+		DefaultLocale:   fleet.DefaultLocale,
+		DefaultMessages: fleet.DefaultMessages,
+		Translations:    translations,
+	}, nil
+}
+
+func (svc *Service) ListTranslations(ctx context.Context) ([]*fleet.Translations, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	translations, err := svc.ds.ListTranslations(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list translations")
+	}
+	return translations, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get translations
+////////////////////////////////////////////////////////////////////////////////
+
+type getTranslationsRequest struct {
+	Locale string `url:"locale"`
+}
+
+type getTranslationsResponse struct {
+	Translations *fleet.Translations `json:"translations,omitempty"`
+	Err          error               `json:"error,omitempty"`
+}
+
+func (r getTranslationsResponse) error() error { return r.Err }
+
+func getTranslationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getTranslationsRequest)
+	translations, err := svc.GetTranslations(ctx, req.Locale)
+	if err != nil {
+		return getTranslationsResponse{Err: err}, nil
+	}
+	return getTranslationsResponse{Translations: translations}, nil
+}
+
+func (svc *Service) GetTranslations(ctx context.Context, locale string) (*fleet.Translations, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	locale, err := fleet.CanonicalLocale(locale)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("locale", err.Error()))
+	}
+	translations, err := svc.ds.Translations(ctx, locale)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get translations")
+	}
+	return translations, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Apply translations
+////////////////////////////////////////////////////////////////////////////////
+
+type applyTranslationsRequest struct {
+	Locale   string                      `url:"locale"`
+	Messages map[fleet.MessageKey]string `json:"messages"`
+}
+
+type applyTranslationsResponse struct {
+	Translations *fleet.Translations `json:"translations,omitempty"`
+	Err          error               `json:"error,omitempty"`
+}
+
+func (r applyTranslationsResponse) error() error { return r.Err }
+
+func applyTranslationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*applyTranslationsRequest)
+	translations, err := svc.ApplyTranslations(ctx, req.Locale, req.Messages)
+	if err != nil {
+		return applyTranslationsResponse{Err: err}, nil
+	}
+	return applyTranslationsResponse{Translations: translations}, nil
+}
+
+func (svc *Service) ApplyTranslations(ctx context.Context, locale string, messages map[fleet.MessageKey]string) (*fleet.Translations, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	t := &fleet.Translations{Locale: locale, Messages: messages}
+	if err := t.Validate(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate translations")
+	}
+	if t.Locale == fleet.DefaultLocale {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("locale", "the default messages cannot be translated"))
+	}
+
+	saved, err := svc.ds.ApplyTranslations(ctx, t)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "apply translations")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeAppliedTranslations{
+		Locale: saved.Locale,
+	}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for applied translations")
+	}
+	return saved, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete translations
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteTranslationsRequest struct {
+	Locale string `url:"locale"`
+}
+
+type deleteTranslationsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteTranslationsResponse) error() error { return r.Err }
+
+func deleteTranslationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*deleteTranslationsRequest)
+	if err := svc.DeleteTranslations(ctx, req.Locale); err != nil {
+		return deleteTranslationsResponse{Err: err}, nil
+	}
+	return deleteTranslationsResponse{}, nil
+}
+
+func (svc *Service) DeleteTranslations(ctx context.Context, locale string) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	locale, err := fleet.CanonicalLocale(locale)
+	if err != nil {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("locale", err.Error()))
+	}
+	if err := svc.ds.DeleteTranslations(ctx, locale); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete translations")
+	}
+
+	if err := svc.ds.NewActivity(ctx, authz.UserFromContext(ctx), fleet.ActivityTypeDeletedTranslations{
+		Locale: locale,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for deleted translations")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslationsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.ListTranslationsFunc = func(ctx context.Context) ([]*fleet.Translations, error) {
+		return nil, nil
+	}
+	ds.TranslationsFunc = func(ctx context.Context, locale string) (*fleet.Translations, error) {
+		return &fleet.Translations{Locale: locale}, nil
+	}
+	ds.ApplyTranslationsFunc = func(ctx context.Context, t *fleet.Translations) (*fleet.Translations, error) {
+		return t, nil
+	}
+	ds.DeleteTranslationsFunc = func(ctx context.Context, locale string) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailRead  bool
+		shouldFailWrite bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false},
+		{"global gitops", &fleet.User{GlobalRole: ptr.String(fleet.RoleGitOps)}, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, true},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, false, true},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListTranslations(ctx)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.GetTranslations(ctx, "fr")
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.ApplyTranslations(ctx, "fr", map[fleet.MessageKey]string{fleet.MessageDesktopMyDevice: "Mon appareil"})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			err = svc.DeleteTranslations(ctx, "fr")
+			checkAuthErr(t, tt.shouldFailWrite, err)
+		})
+	}
+}
+
+func TestApplyTranslations(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	saved := map[string]*fleet.Translations{}
+	ds.ApplyTranslationsFunc = func(ctx context.Context, t *fleet.Translations) (*fleet.Translations, error) {
+		saved[t.Locale] = t
+		return t, nil
+	}
+	ds.DeleteTranslationsFunc = func(ctx context.Context, locale string) error {
+		if _, ok := saved[locale]; !ok {
+			return newNotFoundError()
+		}
+		delete(saved, locale)
+		return nil
+	}
+	var activities []fleet.ActivityDetails
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	// invalid translations
+	cases := []struct {
+		locale   string
+		messages map[fleet.MessageKey]string
+		wantErr  string
+	}{
+		{"not a locale", nil, "invalid locale"},
+		{"en", nil, "the default messages cannot be translated"},
+		{"fr", map[fleet.MessageKey]string{"foo": "bar"}, "unknown message"},
+		{"fr", map[fleet.MessageKey]string{fleet.MessageDesktopMyDeviceIssues: "{n} problèmes"}, "unknown placeholder {n}"},
+	}
+	for _, c := range cases {
+		_, err := svc.ApplyTranslations(ctx, c.locale, c.messages)
+		var invalid *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &invalid)
+		require.ErrorContains(t, err, c.wantErr)
+	}
+	require.Empty(t, saved)
+	require.Empty(t, activities)
+
+	tr, err := svc.ApplyTranslations(ctx, "pt_br", map[fleet.MessageKey]string{
+		fleet.MessageDesktopMyDevice:       "Meu dispositivo",
+		fleet.MessageDesktopMyDeviceIssues: "Meu dispositivo ({count} problemas)",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pt-BR", tr.Locale)
+	require.Contains(t, saved, "pt-BR")
+	require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeAppliedTranslations{Locale: "pt-BR"}}, activities)
+
+	require.NoError(t, svc.DeleteTranslations(ctx, "pt-br"))
+	require.Empty(t, saved)
+	require.Equal(t, fleet.ActivityTypeDeletedTranslations{Locale: "pt-BR"}, activities[1])
+
+	err = svc.DeleteTranslations(ctx, "pt-BR")
+	require.True(t, fleet.IsNotFound(err))
+	require.Len(t, activities, 2)
+}
@@ -481,6 +481,28 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 		hopt.MunkiIssueIDFilter = &mid
 	}
 
+	hostGroupID := r.URL.Query().Get("host_group_id")
+	if hostGroupID != "" {
+		id, err := strconv.ParseUint(hostGroupID, 10, 32)
+		if err != nil {
+			return hopt, ctxerr.Wrap(r.Context(), badRequest(fmt.Sprintf("Invalid host_group_id: %s", hostGroupID)))
+		}
+		gid := uint(id)
+		hopt.HostGroupIDFilter = &gid
+	}
+
+	customFieldName := r.URL.Query().Get("custom_field_name")
+	customFieldValue := r.URL.Query().Get("custom_field_value")
+	if (customFieldName == "") != (customFieldValue == "") {
+		return hopt, ctxerr.Wrap(
+			r.Context(), badRequest("Invalid custom field filter: custom_field_name and custom_field_value must be provided together"),
+		)
+	}
+	if customFieldName != "" {
+		hopt.CustomFieldNameFilter = &customFieldName
+		hopt.CustomFieldValueFilter = &customFieldValue
+	}
+
 	lowDiskSpace := r.URL.Query().Get("low_disk_space")
 	if lowDiskSpace != "" {
 		v, err := strconv.Atoi(lowDiskSpace)
@@ -498,6 +520,39 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 		}
 		hopt.LowDiskSpaceFilter = &v
 	}
+
+	maxHealthScore := r.URL.Query().Get("max_health_score")
+	if maxHealthScore != "" {
+		v, err := strconv.Atoi(maxHealthScore)
+		if err != nil {
+			return hopt, ctxerr.Wrap(r.Context(), badRequest(fmt.Sprintf("Invalid max_health_score: %s", maxHealthScore)))
+		}
+		if v < 0 || v > 100 {
+			return hopt, ctxerr.Wrap(
+				r.Context(), badRequest(
+					fmt.Sprintf(
+						"Invalid max_health_score, must be between 0 and 100: %s", maxHealthScore,
+					),
+				),
+			)
+		}
+		hopt.MaxHealthScoreFilter = &v
+	}
+
+	if cloudProvider := r.URL.Query().Get("cloud_provider"); cloudProvider != "" {
+		p := fleet.CloudProvider(cloudProvider)
+		if !p.IsValid() {
+			return hopt, ctxerr.Wrap(r.Context(), badRequest(fmt.Sprintf("Invalid cloud_provider: %s", cloudProvider)))
+		}
+		hopt.CloudProviderFilter = &p
+	}
+	if cloudRegion := r.URL.Query().Get("cloud_region"); cloudRegion != "" {
+		hopt.CloudRegionFilter = &cloudRegion
+	}
+	if cloudAccountID := r.URL.Query().Get("cloud_account_id"); cloudAccountID != "" {
+		hopt.CloudAccountIDFilter = &cloudAccountID
+	}
+
 	populateSoftware := r.URL.Query().Get("populate_software")
 	if populateSoftware != "" {
 		ps, err := strconv.ParseBool(populateSoftware)
@@ -517,6 +572,28 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 		hopt.PopulatePolicies = pp
 	}
 
+	populateCustomFields := r.URL.Query().Get("populate_custom_fields")
+	if populateCustomFields != "" {
+		pcf, err := strconv.ParseBool(populateCustomFields)
+		if err != nil {
+			return hopt, ctxerr.Wrap(
+				r.Context(), badRequest(fmt.Sprintf("Invalid boolean parameter populate_custom_fields: %s", populateCustomFields)),
+			)
+		}
+		hopt.PopulateCustomFields = pcf
+	}
+
+	populateWarranty := r.URL.Query().Get("populate_warranty")
+	if populateWarranty != "" {
+		pw, err := strconv.ParseBool(populateWarranty)
+		if err != nil {
+			return hopt, ctxerr.Wrap(
+				r.Context(), badRequest(fmt.Sprintf("Invalid boolean parameter populate_warranty: %s", populateWarranty)),
+			)
+		}
+		hopt.PopulateWarranty = pw
+	}
+
 	// cannot combine software_id, software_version_id, and software_title_id
 	var softwareErrorLabel []string
 	if hopt.SoftwareIDFilter != nil {
@@ -129,6 +129,7 @@ func TestListOptionsFromRequest(t *testing.T) {
 }
 
 func TestHostListOptionsFromRequest(t *testing.T) {
+	cloudProviderAWS := fleet.CloudProviderAWS
 	hostListOptionsTests := map[string]struct {
 		// url string to parse
 		url string
@@ -158,7 +159,7 @@ func TestHostListOptionsFromRequest(t *testing.T) {
 				"&os_name=osName&os_version=osVersion&os_version_id=5&disable_failing_policies=1&macos_settings=verified" +
 				"&macos_settings_disk_encryption=enforcing&os_settings=pending&os_settings_disk_encryption=failed" +
 				"&bootstrap_package=installed&mdm_id=6&mdm_name=mdmName&mdm_enrollment_status=automatic" +
-				"&munki_issue_id=7&low_disk_space=99&vulnerability=CVE-2023-42887&populate_policies=true",
+				"&munki_issue_id=7&low_disk_space=99&max_health_score=60&cloud_provider=aws&cloud_region=us-east-1&cloud_account_id=123456789012&vulnerability=CVE-2023-42887&populate_policies=true",
 			hostListOptions: fleet.HostListOptions{
 				ListOptions: fleet.ListOptions{
 					OrderKey:       "foo",
@@ -188,6 +189,10 @@ func TestHostListOptionsFromRequest(t *testing.T) {
 				MDMEnrollmentStatusFilter:         fleet.MDMEnrollStatusAutomatic,
 				MunkiIssueIDFilter:                ptr.Uint(7),
 				LowDiskSpaceFilter:                ptr.Int(99),
+				MaxHealthScoreFilter:              ptr.Int(60),
+				CloudProviderFilter:               &cloudProviderAWS,
+				CloudRegionFilter:                 ptr.String("us-east-1"),
+				CloudAccountIDFilter:              ptr.String("123456789012"),
 				VulnerabilityFilter:               ptr.String("CVE-2023-42887"),
 				PopulatePolicies:                  true,
 			},
@@ -287,6 +292,18 @@ func TestHostListOptionsFromRequest(t *testing.T) {
 			url:          "/foo?low_disk_space=101",
 			errorMessage: "Invalid low_disk_space",
 		},
+		"error in max_health_score (not a number)": {
+			url:          "/foo?max_health_score=foo",
+			errorMessage: "Invalid max_health_score",
+		},
+		"error in max_health_score (too high)": {
+			url:          "/foo?max_health_score=101",
+			errorMessage: "Invalid max_health_score",
+		},
+		"error in cloud_provider": {
+			url:          "/foo?cloud_provider=oracle",
+			errorMessage: "Invalid cloud_provider",
+		},
 		"error in os_name/os_version (os_name missing)": {
 			url:          "/foo?os_version=1.0",
 			errorMessage: "Invalid os_name",
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get usage analytics dashboard
+////////////////////////////////////////////////////////////////////////////////
+
+type getUsageAnalyticsRequest struct {
+	Days int `query:"days,optional"`
+}
+
+type getUsageAnalyticsResponse struct {
+	*fleet.UsageAnalyticsDashboard
+	Err error `json:"error,omitempty"`
+}
+
+func (r getUsageAnalyticsResponse) error() error { return r.Err }
+
+func getUsageAnalyticsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (errorer, error) {
+	req := request.(*getUsageAnalyticsRequest)
+	dashboard, err := svc.GetUsageAnalytics(ctx, req.Days)
+	if err != nil {
+		return getUsageAnalyticsResponse{Err: err}, nil
+	}
+	return getUsageAnalyticsResponse{UsageAnalyticsDashboard: dashboard}, nil
+}
+
+func (svc *Service) GetUsageAnalytics(ctx context.Context, days int) (*fleet.UsageAnalyticsDashboard, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.UsageAnalytics{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	maxDays := int(fleet.UsageAnalyticsRetention / (24 * time.Hour))
+	if days == 0 {
+		days = fleet.UsageAnalyticsDefaultDays
+	}
+	if days < 1 || days > maxDays {
+		return nil, fleet.NewInvalidArgumentError("days", "must be between 1 and 365")
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if !appConfig.ServerSettings.DetailedUsageAnalytics {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "Detailed usage analytics are disabled. Set server_settings.detailed_usage_analytics to true to collect them.",
+		})
+	}
+
+	// the current day is included
+	since := svc.clock.Now().UTC().AddDate(0, 0, -(days - 1))
+	dashboard, err := svc.ds.GetUsageAnalyticsDashboard(ctx, since, svc.config)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get usage analytics dashboard")
+	}
+	return dashboard, nil
+}
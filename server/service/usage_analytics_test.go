@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUsageAnalytics(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	appConfig := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	var gotSince time.Time
+	ds.GetUsageAnalyticsDashboardFunc = func(ctx context.Context, since time.Time, config config.FleetConfig) (*fleet.UsageAnalyticsDashboard, error) {
+		gotSince = since
+		return &fleet.UsageAnalyticsDashboard{Since: since}, nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global maintainer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleMaintainer)}, true},
+		{"global observer", &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}, true},
+		{"team admin", &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+			appConfig.ServerSettings.DetailedUsageAnalytics = true
+			_, err := svc.GetUsageAnalytics(ctx, 0)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	// the dashboard covers the last days, including the current one
+	appConfig.ServerSettings.DetailedUsageAnalytics = true
+	_, err := svc.GetUsageAnalytics(ctx, 0)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().UTC().AddDate(0, 0, -(fleet.UsageAnalyticsDefaultDays-1)), gotSince, time.Minute)
+	_, err = svc.GetUsageAnalytics(ctx, 1)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().UTC(), gotSince, time.Minute)
+
+	for _, days := range []int{-1, 366} {
+		_, err = svc.GetUsageAnalytics(ctx, days)
+		var invalid *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &invalid)
+	}
+
+	// the detailed usage analytics are opt-in
+	ds.GetUsageAnalyticsDashboardFuncInvoked = false
+	appConfig.ServerSettings.DetailedUsageAnalytics = false
+	_, err = svc.GetUsageAnalytics(ctx, 0)
+	require.ErrorContains(t, err, "Detailed usage analytics are disabled")
+	require.False(t, ds.GetUsageAnalyticsDashboardFuncInvoked)
+}
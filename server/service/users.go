@@ -89,6 +89,11 @@ func (svc *Service) CreateUser(ctx context.Context, p fleet.UserPayload) (*fleet
 		p.AdminForcedPasswordReset = ptr.Bool(true)
 	}
 
+	// the users created by the user of a tenant join the tenant
+	if creator := authz.UserFromContext(ctx); creator != nil {
+		p.TenantID = creator.TenantID
+	}
+
 	return svc.NewUser(ctx, p)
 }
 
@@ -122,6 +127,7 @@ func (svc *Service) CreateUserFromInvite(ctx context.Context, p fleet.UserPayloa
 	// set the payload role property based on an existing invite.
 	p.GlobalRole = invite.GlobalRole.Ptr()
 	p.Teams = &invite.Teams
+	p.TenantID = invite.TenantID
 
 	user, err := svc.NewUser(ctx, p)
 	if err != nil {
@@ -173,6 +179,11 @@ func (svc *Service) ListUsers(ctx context.Context, opt fleet.UserListOptions) ([
 		return nil, err
 	}
 
+	// the users of a tenant only see the users of their tenant
+	if vc, ok := viewer.FromContext(ctx); ok && vc.User.TenantID != nil {
+		opt.TenantID = vc.User.TenantID
+	}
+
 	return svc.ds.ListUsers(ctx, opt)
 }
 
@@ -606,11 +617,7 @@ func getInfoAboutSessionsForUserEndpoint(ctx context.Context, request interface{
 	}
 	var resp getInfoAboutSessionsForUserResponse
 	for _, session := range sessions {
-		resp.Sessions = append(resp.Sessions, getInfoAboutSessionResponse{
-			SessionID: session.ID,
-			UserID:    session.UserID,
-			CreatedAt: session.CreatedAt,
-		})
+		resp.Sessions = append(resp.Sessions, newGetInfoAboutSessionResponse(session))
 	}
 	return resp, nil
 }
@@ -794,7 +801,7 @@ func (svc *Service) modifyEmailAddress(ctx context.Context, user *fleet.User, em
 	if err != nil {
 		return err
 	}
-	config, err := svc.ds.AppConfig(ctx)
+	config, err := svc.appConfigForUser(ctx, user)
 	if err != nil {
 		return err
 	}
@@ -1053,7 +1060,7 @@ func (svc *Service) RequestPasswordReset(ctx context.Context, email string) erro
 		return err
 	}
 
-	config, err := svc.ds.AppConfig(ctx)
+	config, err := svc.appConfigForUser(ctx, user)
 	if err != nil {
 		return err
 	}
@@ -1063,16 +1070,19 @@ func (svc *Service) RequestPasswordReset(ctx context.Context, email string) erro
 		smtpSettings = *config.SMTPSettings
 	}
 
-	resetEmail := fleet.Email{
-		Subject:      "Reset Your Fleet Password",
+	resetEmail, customTemplate, err := svc.emailWithTemplate(ctx, fleet.EmailTemplatePasswordReset, fleet.Email{
 		To:           []string{user.Email},
 		SMTPSettings: smtpSettings,
 		ServerURL:    config.ServerSettings.ServerURL,
-		Mailer: &mail.PasswordResetMailer{
-			BaseURL:  template.URL(config.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
-			AssetURL: getAssetURL(),
-			Token:    token,
-		},
+	})
+	if err != nil {
+		return err
+	}
+	resetEmail.Mailer = &mail.PasswordResetMailer{
+		BaseURL:        template.URL(config.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+		AssetURL:       getAssetURL(),
+		Token:          token,
+		CustomTemplate: customTemplate,
 	}
 
 	err = svc.mailService.SendEmail(resetEmail)
@@ -430,6 +430,90 @@ func TestUserAuth(t *testing.T) {
 	}
 }
 
+func TestUsersTenant(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return []*fleet.TeamSummary{{ID: 1}, {ID: 2}}, nil
+	}
+	ds.InviteByEmailFunc = func(ctx context.Context, email string) (*fleet.Invite, error) {
+		return nil, newNotFoundError()
+	}
+	var created *fleet.User
+	ds.NewUserFunc = func(ctx context.Context, user *fleet.User) (*fleet.User, error) {
+		created = user
+		return user, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		switch id {
+		case 10:
+			return &fleet.User{ID: id, TenantID: ptr.Uint(1)}, nil
+		case 11:
+			return &fleet.User{ID: id, TenantID: ptr.Uint(2)}, nil
+		default:
+			return &fleet.User{ID: id}, nil
+		}
+	}
+	ds.DeleteUserFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	var listOpt fleet.UserListOptions
+	ds.ListUsersFunc = func(ctx context.Context, opt fleet.UserListOptions) ([]*fleet.User, error) {
+		listOpt = opt
+		return nil, nil
+	}
+
+	tenantAdmin := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin), TenantID: ptr.Uint(1), TenantTeamIDs: []uint{1}}
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: tenantAdmin})
+
+	// the users created by a tenant user join its tenant
+	_, err := svc.CreateUser(ctx, fleet.UserPayload{
+		Name:     ptr.String("Some Name"),
+		Email:    ptr.String("some@example.com"),
+		Password: ptr.String(test.GoodPassword),
+		Teams:    &[]fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, created)
+	require.NotNil(t, created.TenantID)
+	require.Equal(t, uint(1), *created.TenantID)
+
+	// and cannot get roles on the teams of another tenant
+	_, err = svc.CreateUser(ctx, fleet.UserPayload{
+		Name:     ptr.String("Other Name"),
+		Email:    ptr.String("other@example.com"),
+		Password: ptr.String(test.GoodPassword),
+		Teams:    &[]fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleObserver}},
+	})
+	checkAuthErr(t, true, err)
+
+	// the users of other tenants or outside any tenant are not accessible by ID
+	for _, tc := range []struct {
+		userID     uint
+		shouldFail bool
+	}{
+		{10, false},
+		{11, true},
+		{12, true},
+	} {
+		_, err := svc.User(ctx, tc.userID)
+		checkAuthErr(t, tc.shouldFail, err)
+
+		err = svc.DeleteUser(ctx, tc.userID)
+		checkAuthErr(t, tc.shouldFail, err)
+	}
+
+	// lists are filtered by tenant
+	_, err = svc.ListUsers(ctx, fleet.UserListOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, listOpt.TenantID)
+	require.Equal(t, uint(1), *listOpt.TenantID)
+}
+
 func TestModifyUserEmail(t *testing.T) {
 	user := &fleet.User{
 		ID:    3,
@@ -797,7 +881,7 @@ func TestPerformRequiredPasswordReset(t *testing.T) {
 
 			ctx = refreshCtx(t, ctx, user, ds, nil)
 
-			session, err := ds.NewSession(context.Background(), user.ID, "")
+			session, err := ds.NewSession(context.Background(), user.ID, "", "", "")
 			require.Nil(t, err)
 			ctx = refreshCtx(t, ctx, user, ds, session)
 
@@ -903,7 +987,7 @@ func TestAuthenticatedUser(t *testing.T) {
 	svc, ctx := newTestService(t, ds, nil, nil)
 	admin1, err := ds.UserByEmail(context.Background(), "admin1@example.com")
 	require.NoError(t, err)
-	admin1Session, err := ds.NewSession(context.Background(), admin1.ID, "admin1")
+	admin1Session, err := ds.NewSession(context.Background(), admin1.ID, "admin1", "", "")
 	require.NoError(t, err)
 
 	ctx = viewer.NewContext(ctx, viewer.Viewer{User: admin1, Session: admin1Session})
@@ -83,16 +83,21 @@ func (svc *Service) ListVulnerabilities(ctx context.Context, opt fleet.VulnListO
 	}
 
 	for i, vuln := range vulns {
-		if vuln.Source == fleet.MSRCSource {
-			vulns[i].DetailsLink = fmt.Sprintf("https://msrc.microsoft.com/update-guide/en-US/vulnerability/%s", vuln.CVE.CVE)
-		} else {
-			vulns[i].DetailsLink = fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", vuln.CVE.CVE)
-		}
+		vulns[i].DetailsLink = vulnerabilityDetailsLink(vuln)
 	}
 
 	return vulns, meta, nil
 }
 
+// vulnerabilityDetailsLink returns the URL of the page describing the
+// vulnerability, on the website of its source.
+func vulnerabilityDetailsLink(vuln fleet.VulnerabilityWithMetadata) string {
+	if vuln.Source == fleet.MSRCSource {
+		return fmt.Sprintf("https://msrc.microsoft.com/update-guide/en-US/vulnerability/%s", vuln.CVE.CVE)
+	}
+	return fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", vuln.CVE.CVE)
+}
+
 func (svc *Service) CountVulnerabilities(ctx context.Context, opts fleet.VulnListOptions) (uint, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.AuthzSoftwareInventory{
 		TeamID: &opts.TeamID,
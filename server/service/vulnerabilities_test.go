@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
@@ -73,6 +74,14 @@ func TestVulnerabilitesAuth(t *testing.T) {
 		return true, nil
 	}
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+
 	for _, tc := range []struct {
 		name                 string
 		user                 *fleet.User
@@ -178,6 +187,67 @@ func TestVulnerabilitesAuth(t *testing.T) {
 
 			_, err = svc.Vulnerability(ctx, "CVE-2019-1234", ptr.Uint(1), false)
 			checkAuthErr(t, tc.shouldFailTeamRead, err)
+
+			_, err = svc.GetVulnerabilitySLAReport(ctx, nil)
+			checkAuthErr(t, tc.shouldFailGlobalRead, err)
+
+			_, err = svc.GetVulnerabilitySLAReport(ctx, ptr.Uint(1))
+			checkAuthErr(t, tc.shouldFailTeamRead, err)
 		})
 	}
 }
+
+func TestGetVulnerabilitySLAReport(t *testing.T) {
+	ds := new(mock.Store)
+	now := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+	svc, ctx := newTestServiceWithClock(t, ds, nil, nil, clock.NewMockClock(now))
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	globalSLA := fleet.VulnerabilitySLASettings{
+		Enable:         true,
+		CriticalDays:   14,
+		HighDays:       30,
+		DestinationURL: "https://example.com/sla",
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{VulnerabilitySLA: globalSLA}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{
+			InheritedSettings: []string{"vulnerability_sla"},
+		}}, nil
+	}
+	ds.ListVulnerabilitySLABreachesFunc = func(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) ([]*fleet.VulnerabilitySLABreach, error) {
+		require.Equal(t, now, opts.Now)
+		require.False(t, opts.NotNotifiedOnly)
+		require.Equal(t, globalSLA, opts.SLA)
+		return []*fleet.VulnerabilitySLABreach{
+			{CVE: "CVE-2024-0001", CVSSScore: 9.8, Severity: fleet.VulnerabilitySeverityCritical, SLADays: 14, HostsCount: 3},
+			{CVE: "CVE-2024-0002", CVSSScore: 9.1, Severity: fleet.VulnerabilitySeverityCritical, SLADays: 14, HostsCount: 1},
+			{CVE: "CVE-2024-0003", CVSSScore: 7.5, Severity: fleet.VulnerabilitySeverityHigh, SLADays: 30, HostsCount: 2},
+		}, nil
+	}
+
+	for _, teamID := range []*uint{nil, ptr.Uint(1)} {
+		report, err := svc.GetVulnerabilitySLAReport(ctx, teamID)
+		require.NoError(t, err)
+		require.Equal(t, teamID, report.TeamID)
+		require.Empty(t, report.SLA.DestinationURL)
+		require.Equal(t, map[fleet.VulnerabilitySeverity]uint{
+			fleet.VulnerabilitySeverityCritical: 4,
+			fleet.VulnerabilitySeverityHigh:     2,
+			fleet.VulnerabilitySeverityMedium:   0,
+			fleet.VulnerabilitySeverityLow:      0,
+		}, report.OverdueCounts)
+		require.Len(t, report.Vulnerabilities, 3)
+	}
+
+	// disabled SLAs, nothing is overdue
+	globalSLA.Enable = false
+	ds.ListVulnerabilitySLABreachesFuncInvoked = false
+	report, err := svc.GetVulnerabilitySLAReport(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, report.Vulnerabilities)
+	require.Zero(t, report.OverdueCounts[fleet.VulnerabilitySeverityCritical])
+	require.False(t, ds.ListVulnerabilitySLABreachesFuncInvoked)
+}
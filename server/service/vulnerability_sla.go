@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+type getVulnerabilitySLAReportRequest struct {
+	// TeamID is the team of the hosts, 0 or absent for the hosts with no team.
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type getVulnerabilitySLAReportResponse struct {
+	*fleet.VulnerabilitySLAReport
+	Err error `json:"error,omitempty"`
+}
+
+func (r getVulnerabilitySLAReportResponse) error() error { return r.Err }
+
+func getVulnerabilitySLAReportEndpoint(ctx context.Context, req interface{}, svc fleet.Service) (errorer, error) {
+	request := req.(*getVulnerabilitySLAReportRequest)
+	teamID := request.TeamID
+	if teamID != nil && *teamID == 0 {
+		teamID = nil
+	}
+	report, err := svc.GetVulnerabilitySLAReport(ctx, teamID)
+	if err != nil {
+		return getVulnerabilitySLAReportResponse{Err: err}, nil
+	}
+	return getVulnerabilitySLAReportResponse{VulnerabilitySLAReport: report}, nil
+}
+
+func (svc *Service) GetVulnerabilitySLAReport(ctx context.Context, teamID *uint) (*fleet.VulnerabilitySLAReport, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AuthzSoftwareInventory{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	sla := appConfig.VulnerabilitySLA
+	if teamID != nil {
+		team, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+		team.ApplyInheritedSettings(appConfig)
+		sla = team.Config.VulnerabilitySLA
+	}
+
+	report := &fleet.VulnerabilitySLAReport{
+		TeamID:          teamID,
+		SLA:             sla,
+		OverdueCounts:   make(map[fleet.VulnerabilitySeverity]uint, len(fleet.VulnerabilitySeverities)),
+		Vulnerabilities: []*fleet.VulnerabilitySLABreach{},
+	}
+	for _, severity := range fleet.VulnerabilitySeverities {
+		report.OverdueCounts[severity] = 0
+	}
+	// the destination URL of the escalation webhook is not reported
+	report.SLA.DestinationURL = ""
+	if !sla.Enable {
+		return report, nil
+	}
+
+	breaches, err := svc.ds.ListVulnerabilitySLABreaches(ctx, fleet.VulnerabilitySLABreachOptions{
+		TeamID: teamID,
+		SLA:    sla,
+		Now:    svc.clock.Now(),
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list vulnerability sla breaches")
+	}
+	for _, b := range breaches {
+		report.OverdueCounts[b.Severity] += b.HostsCount
+	}
+	report.Vulnerabilities = breaches
+	return report, nil
+}
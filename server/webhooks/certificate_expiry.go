@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// TriggerCertificateExpiryWebhook sends the certificates that expire within
+// the configured number of days and that are found on more hosts than the
+// configured threshold to the certificate expiry webhook, if enabled.
+func TriggerCertificateExpiryWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	now time.Time,
+) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting app config")
+	}
+
+	settings := appConfig.WebhookSettings.CertificateExpiryWebhook
+	if !settings.Enable {
+		return nil
+	}
+
+	level.Debug(logger).Log("enable_certificate_expiry_webhook", "true")
+
+	certs, err := ds.ListExpiringCertificates(ctx, now, now.AddDate(0, 0, settings.DaysBeforeExpiry), uint(settings.HostsThreshold))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "listing expiring certificates")
+	}
+	if len(certs) == 0 {
+		return nil
+	}
+
+	type certificatePayload struct {
+		SHA1          string    `json:"sha1"`
+		CommonName    string    `json:"common_name"`
+		Subject       string    `json:"subject"`
+		Issuer        string    `json:"issuer"`
+		NotValidAfter time.Time `json:"not_valid_after"`
+		HostsCount    uint      `json:"hosts_count"`
+	}
+	certsPayload := make([]certificatePayload, 0, len(certs))
+	for _, c := range certs {
+		certsPayload = append(certsPayload, certificatePayload{
+			SHA1:          c.SHA1,
+			CommonName:    c.CommonName,
+			Subject:       c.Subject,
+			Issuer:        c.Issuer,
+			NotValidAfter: c.NotValidAfter,
+			HostsCount:    c.HostsCount,
+		})
+	}
+
+	message := fmt.Sprintf(
+		"%d certificate(s) found on more than %d hosts expire within %d days. "+
+			"You've been sent this message because the Certificate expiry webhook is enabled in your Fleet instance.",
+		len(certs), settings.HostsThreshold, settings.DaysBeforeExpiry,
+	)
+	payload := map[string]interface{}{
+		"text": message,
+		"data": map[string]interface{}{
+			"days_before_expiry": settings.DaysBeforeExpiry,
+			"hosts_threshold":    settings.HostsThreshold,
+			"certificates":       certsPayload,
+		},
+	}
+
+	url := settings.DestinationURL
+	if err := server.PostJSONWithTimeout(ctx, url, &payload); err != nil {
+		return ctxerr.Wrapf(ctx, err, "posting to %s", url)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerCertificateExpiryWebhook(t *testing.T) {
+	ds := new(mock.Store)
+
+	requestBody := ""
+	count := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBodyBytes, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requestBody = string(requestBodyBytes)
+		count++
+	}))
+	defer ts.Close()
+
+	ac := &fleet.AppConfig{
+		WebhookSettings: fleet.WebhookSettings{
+			CertificateExpiryWebhook: fleet.CertificateExpiryWebhookSettings{
+				Enable:           true,
+				DestinationURL:   ts.URL,
+				DaysBeforeExpiry: 30,
+				HostsThreshold:   10,
+			},
+		},
+	}
+	ds.AppConfigFunc = func(context.Context) (*fleet.AppConfig, error) {
+		return ac, nil
+	}
+
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	var certs []*fleet.CertificateInventoryItem
+	ds.ListExpiringCertificatesFunc = func(ctx context.Context, from, to time.Time, hostsThreshold uint) ([]*fleet.CertificateInventoryItem, error) {
+		assert.Equal(t, now, from)
+		assert.Equal(t, now.AddDate(0, 0, 30), to)
+		assert.EqualValues(t, 10, hostsThreshold)
+		return certs, nil
+	}
+
+	// no expiring certificate, nothing is sent
+	require.NoError(t, TriggerCertificateExpiryWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.Equal(t, 0, count)
+
+	certs = []*fleet.CertificateInventoryItem{
+		{
+			SHA1:          "0123456789abcdef0123456789abcdef01234567",
+			CommonName:    "Example Root CA",
+			Subject:       "/CN=Example Root CA",
+			Issuer:        "/CN=Example Root CA",
+			CA:            true,
+			NotValidAfter: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC),
+			HostsCount:    42,
+		},
+	}
+	require.NoError(t, TriggerCertificateExpiryWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.Equal(
+		t,
+		`{"data":{"certificates":[{"sha1":"0123456789abcdef0123456789abcdef01234567","common_name":"Example Root CA","subject":"/CN=Example Root CA","issuer":"/CN=Example Root CA","not_valid_after":"2024-05-20T00:00:00Z","hosts_count":42}],"days_before_expiry":30,"hosts_threshold":10},"text":"1 certificate(s) found on more than 10 hosts expire within 30 days. You've been sent this message because the Certificate expiry webhook is enabled in your Fleet instance."}`,
+		requestBody,
+	)
+	assert.Equal(t, 1, count)
+
+	// disabled webhook, nothing is sent
+	ac.WebhookSettings.CertificateExpiryWebhook.Enable = false
+	require.NoError(t, TriggerCertificateExpiryWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.Equal(t, 1, count)
+}
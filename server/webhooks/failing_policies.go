@@ -3,9 +3,7 @@ package webhooks
 import (
 	"context"
 	"net/url"
-	"path"
 	"sort"
-	"strconv"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server"
@@ -20,6 +18,7 @@ import (
 // send, the corresponding hosts are removed from the failing policies set.
 func SendFailingPoliciesBatchedPOSTs(
 	ctx context.Context,
+	ds fleet.Datastore,
 	policy *fleet.Policy,
 	failingPoliciesSet fleet.FailingPolicySet,
 	hostBatchSize int,
@@ -40,26 +39,17 @@ func SendFailingPoliciesBatchedPOSTs(
 		return hosts[i].ID < hosts[j].ID
 	})
 
-	if hostBatchSize == 0 {
-		hostBatchSize = len(hosts)
+	hostIDs := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIDs = append(hostIDs, host.ID)
+	}
+	healthScores, err := ds.GetHostHealthScores(ctx, hostIDs)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "get health scores of hosts failing policy %d", policy.ID)
 	}
-	for i := 0; i < len(hosts); i += hostBatchSize {
-		end := i + hostBatchSize
-		if end > len(hosts) {
-			end = len(hosts)
-		}
-		batch := hosts[i:end]
-
-		failingHosts := make([]failingHost, len(batch))
-		for i, host := range batch {
-			failingHosts[i] = makeFailingHost(host, serverURL)
-		}
 
-		payload := failingPoliciesPayload{
-			Timestamp:    now,
-			Policy:       policy,
-			FailingHosts: failingHosts,
-		}
+	for _, batch := range fleet.BatchPolicySetHosts(hosts, hostBatchSize) {
+		payload := fleet.NewFailingPoliciesWebhookPayload(policy, batch, healthScores, serverURL, now)
 		level.Debug(logger).Log("payload", payload, "url", server.MaskSecretURLParams(webhookURL.String()), "batch", len(batch))
 		if err := server.PostJSONWithTimeout(ctx, webhookURL.String(), &payload); err != nil {
 			return ctxerr.Wrapf(ctx, server.MaskURLError(err), "posting to %q", server.MaskSecretURLParams(webhookURL.String()))
@@ -70,27 +60,3 @@ func SendFailingPoliciesBatchedPOSTs(
 	}
 	return nil
 }
-
-type failingPoliciesPayload struct {
-	Timestamp    time.Time     `json:"timestamp"`
-	Policy       *fleet.Policy `json:"policy"`
-	FailingHosts []failingHost `json:"hosts"`
-}
-
-type failingHost struct {
-	ID          uint   `json:"id"`
-	Hostname    string `json:"hostname"`
-	DisplayName string `json:"display_name"`
-	URL         string `json:"url"`
-}
-
-func makeFailingHost(host fleet.PolicySetHost, serverURL *url.URL) failingHost {
-	u := *serverURL
-	u.Path = path.Join(serverURL.Path, "hosts", strconv.FormatUint(uint64(host.ID), 10))
-	return failingHost{
-		ID:          host.ID,
-		Hostname:    host.Hostname,
-		DisplayName: host.DisplayName,
-		URL:         u.String(),
-	}
-}
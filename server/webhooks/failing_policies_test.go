@@ -25,6 +25,9 @@ import (
 
 func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 	ds := new(mock.Store)
+	ds.GetHostHealthScoresFunc = func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+		return map[uint]*fleet.HostHealthScore{1: {HostID: 1, Score: 75}}, nil
+	}
 
 	requestBody := ""
 
@@ -99,7 +102,7 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, kitlog.NewNopLogger())
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, kitlog.NewNopLogger())
 	})
 	require.NoError(t, err)
 	timestamp, err := mockClock.MarshalJSON()
@@ -132,7 +135,8 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
             "id": 1,
             "hostname": "host1.example",
             "display_name": "display1",
-            "url": "https://fleet.example.com/hosts/1"
+            "url": "https://fleet.example.com/hosts/1",
+            "health_score": 75
         },
         {
             "id": 2,
@@ -155,7 +159,7 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, kitlog.NewNopLogger())
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, kitlog.NewNopLogger())
 	})
 	require.NoError(t, err)
 	assert.Empty(t, requestBody)
@@ -178,6 +182,9 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 	})
 
 	ds := new(mock.Store)
+	ds.GetHostHealthScoresFunc = func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+		return nil, nil
+	}
 
 	teamID := uint(1)
 
@@ -283,7 +290,7 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, kitlog.NewNopLogger())
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, kitlog.NewNopLogger())
 	})
 	require.NoError(t, err)
 
@@ -336,13 +343,17 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, kitlog.NewNopLogger())
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, kitlog.NewNopLogger())
 	})
 	require.NoError(t, err)
 	assert.Empty(t, webhookBody)
 }
 
 func TestSendBatchedPOSTs(t *testing.T) {
+	ds := new(mock.Store)
+	ds.GetHostHealthScoresFunc = func(ctx context.Context, hostIDs []uint) (map[uint]*fleet.HostHealthScore, error) {
+		return nil, nil
+	}
 	allHosts := []uint{}
 	requestCount := 0
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -350,7 +361,7 @@ func TestSendBatchedPOSTs(t *testing.T) {
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
-		var payload failingPoliciesPayload
+		var payload fleet.FailingPoliciesWebhookPayload
 		err = json.Unmarshal(b, &payload)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -457,6 +468,7 @@ func TestSendBatchedPOSTs(t *testing.T) {
 
 			err = SendFailingPoliciesBatchedPOSTs(
 				context.Background(),
+				ds,
 				p,
 				failingPolicySet,
 				tc.batchSize,
@@ -76,6 +76,10 @@ func processWebhook(ctx context.Context, ds fleet.Datastore, teamID *uint, setti
 }
 
 func triggerTeamHostStatusWebhook(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting app config")
+	}
 
 	teams, err := ds.TeamsSummary(ctx)
 	if err != nil {
@@ -90,6 +94,7 @@ func triggerTeamHostStatusWebhook(ctx context.Context, ds fleet.Datastore, logge
 			multiErr = multierror.Append(multiErr, ctxerr.Wrap(ctx, err, "getting team"))
 			continue
 		}
+		team.ApplyInheritedSettings(appConfig)
 		if team.Config.WebhookSettings.HostStatusWebhook == nil || !team.Config.WebhookSettings.HostStatusWebhook.Enable {
 			continue
 		}
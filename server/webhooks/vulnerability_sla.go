@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hashicorp/go-multierror"
+)
+
+// TriggerVulnerabilitySLAWebhook sends the vulnerabilities that became
+// overdue since the last run to the destination URL of the vulnerability SLAs,
+// for the hosts with no team and for each team. A breach is only sent once per
+// host and vulnerability.
+func TriggerVulnerabilitySLAWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	now time.Time,
+) error {
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting app config")
+	}
+
+	multiErr := &multierror.Error{}
+	multiErr = multierror.Append(multiErr, processVulnerabilitySLAWebhook(ctx, ds, logger, nil, "", appConfig.VulnerabilitySLA, now))
+
+	teams, err := ds.TeamsSummary(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting teams summary")
+	}
+	// We try to send a webhook for each team. If one team fails, we continue.
+	for _, teamSummary := range teams {
+		id := teamSummary.ID
+		team, err := ds.Team(ctx, id)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, ctxerr.Wrap(ctx, err, "getting team"))
+			continue
+		}
+		team.ApplyInheritedSettings(appConfig)
+		multiErr = multierror.Append(multiErr, processVulnerabilitySLAWebhook(ctx, ds, logger, &id, team.Name, team.Config.VulnerabilitySLA, now))
+	}
+
+	return multiErr.ErrorOrNil()
+}
+
+func processVulnerabilitySLAWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	teamID *uint,
+	teamName string,
+	sla fleet.VulnerabilitySLASettings,
+	now time.Time,
+) error {
+	if !sla.Enable || sla.DestinationURL == "" {
+		return nil
+	}
+
+	level.Debug(logger).Log("team_id", fmt.Sprint(teamID), "enable_vulnerability_sla_webhook", "true")
+
+	opts := fleet.VulnerabilitySLABreachOptions{
+		TeamID:          teamID,
+		SLA:             sla,
+		Now:             now,
+		NotNotifiedOnly: true,
+	}
+	breaches, err := ds.ListVulnerabilitySLABreaches(ctx, opts)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "listing vulnerability sla breaches")
+	}
+	if len(breaches) == 0 {
+		return nil
+	}
+
+	var hostsCount uint
+	for _, b := range breaches {
+		hostsCount += b.HostsCount
+	}
+	scope := "hosts with no team"
+	if teamID != nil {
+		scope = fmt.Sprintf("hosts of the %q team", teamName)
+	}
+	message := fmt.Sprintf(
+		"%d vulnerabilities exceeded their remediation SLA on %d %s (counted once per host). "+
+			"You've been sent this message because the Vulnerability SLA webhook is enabled in your Fleet instance.",
+		len(breaches), hostsCount, scope,
+	)
+	// the destination URL is not part of the payload
+	payloadSLA := sla
+	payloadSLA.DestinationURL = ""
+	data := map[string]interface{}{
+		"sla":             payloadSLA,
+		"vulnerabilities": breaches,
+	}
+	if teamID != nil {
+		data["team_id"] = *teamID
+	}
+	payload := map[string]interface{}{
+		"text": message,
+		"data": data,
+	}
+
+	url := sla.DestinationURL
+	if err := server.PostJSONWithTimeout(ctx, url, &payload); err != nil {
+		return ctxerr.Wrapf(ctx, err, "posting to %s", url)
+	}
+
+	// the breaches that happened since they were listed will be sent on the
+	// next run
+	if err := ds.MarkVulnerabilitySLABreachesNotified(ctx, opts); err != nil {
+		return ctxerr.Wrap(ctx, err, "marking vulnerability sla breaches notified")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerVulnerabilitySLAWebhook(t *testing.T) {
+	ds := new(mock.Store)
+
+	var requestBodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBodyBytes, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requestBodies = append(requestBodies, string(requestBodyBytes))
+	}))
+	defer ts.Close()
+
+	ac := &fleet.AppConfig{
+		VulnerabilitySLA: fleet.VulnerabilitySLASettings{
+			Enable:         true,
+			CriticalDays:   14,
+			HighDays:       30,
+			DestinationURL: ts.URL,
+		},
+	}
+	ds.AppConfigFunc = func(context.Context) (*fleet.AppConfig, error) {
+		return ac, nil
+	}
+
+	// team 1 inherits the global SLAs, team 2 has its own SLAs without webhook
+	teams := map[uint]*fleet.Team{
+		1: {ID: 1, Name: "team1", Config: fleet.TeamConfig{
+			InheritedSettings: []string{"vulnerability_sla"},
+		}},
+		2: {ID: 2, Name: "team2", Config: fleet.TeamConfig{
+			VulnerabilitySLA: fleet.VulnerabilitySLASettings{Enable: true, CriticalDays: 7},
+		}},
+	}
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return []*fleet.TeamSummary{{ID: 1, Name: "team1"}, {ID: 2, Name: "team2"}}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		// return a copy, the inherited settings are applied to it
+		team := *teams[tid]
+		return &team, nil
+	}
+
+	now := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+	breaches := map[uint][]*fleet.VulnerabilitySLABreach{}
+	ds.ListVulnerabilitySLABreachesFunc = func(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) ([]*fleet.VulnerabilitySLABreach, error) {
+		assert.Equal(t, now, opts.Now)
+		assert.True(t, opts.NotNotifiedOnly)
+		assert.Equal(t, 14, opts.SLA.CriticalDays)
+		var teamID uint
+		if opts.TeamID != nil {
+			teamID = *opts.TeamID
+		}
+		return breaches[teamID], nil
+	}
+	var notified []*uint
+	ds.MarkVulnerabilitySLABreachesNotifiedFunc = func(ctx context.Context, opts fleet.VulnerabilitySLABreachOptions) error {
+		assert.Equal(t, now, opts.Now)
+		notified = append(notified, opts.TeamID)
+		return nil
+	}
+
+	// no breach, nothing is sent
+	require.NoError(t, TriggerVulnerabilitySLAWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.Empty(t, requestBodies)
+	assert.Empty(t, notified)
+	assert.True(t, ds.ListVulnerabilitySLABreachesFuncInvoked)
+
+	breaches[1] = []*fleet.VulnerabilitySLABreach{
+		{
+			CVE:             "CVE-2024-0001",
+			CVSSScore:       9.8,
+			Severity:        fleet.VulnerabilitySeverityCritical,
+			SLADays:         14,
+			HostsCount:      3,
+			FirstDetectedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	require.NoError(t, TriggerVulnerabilitySLAWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	require.Len(t, requestBodies, 1)
+	assert.JSONEq(
+		t,
+		`{"data":{"sla":{"enable":true,"critical_days":14,"high_days":30,"medium_days":0,"low_days":0,"destination_url":""},"team_id":1,"vulnerabilities":[{"cve":"CVE-2024-0001","cvss_score":9.8,"severity":"critical","sla_days":14,"hosts_count":3,"first_detected_at":"2024-06-01T00:00:00Z"}]},"text":"1 vulnerabilities exceeded their remediation SLA on 3 hosts of the \"team1\" team (counted once per host). You've been sent this message because the Vulnerability SLA webhook is enabled in your Fleet instance."}`,
+		requestBodies[0],
+	)
+	assert.Equal(t, []*uint{ptr.Uint(1)}, notified)
+
+	// the breaches are not marked notified if they could not be sent
+	requestBodies, notified = nil, nil
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	require.Error(t, TriggerVulnerabilitySLAWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.Empty(t, notified)
+
+	// disabled SLAs, nothing is listed
+	ac.VulnerabilitySLA.Enable = false
+	ds.ListVulnerabilitySLABreachesFuncInvoked = false
+	require.NoError(t, TriggerVulnerabilitySLAWebhook(context.Background(), ds, kitlog.NewNopLogger(), now))
+	assert.False(t, ds.ListVulnerabilitySLABreachesFuncInvoked)
+}
@@ -29,8 +29,13 @@ const (
 	AppleMDMPostDEPEnrollmentTask    AppleMDMTask = "post_dep_enrollment"
 	AppleMDMPostManualEnrollmentTask AppleMDMTask = "post_manual_enrollment"
 	AppleMDMPostDEPReleaseDeviceTask AppleMDMTask = "post_dep_release_device"
+	AppleMDMRetryFleetdInstallTask   AppleMDMTask = "retry_fleetd_install"
 )
 
+// fleetdInstallRetryDelay is the delay before the command to install fleetd is
+// re-sent after a transient failure.
+const fleetdInstallRetryDelay = 5 * time.Minute
+
 // AppleMDM is the job processor for the apple_mdm job.
 type AppleMDM struct {
 	Datastore fleet.Datastore
@@ -78,13 +83,17 @@ func (a *AppleMDM) Run(ctx context.Context, argsJSON json.RawMessage) error {
 		err := a.runPostDEPReleaseDevice(ctx, args)
 		return ctxerr.Wrap(ctx, err, "running post Apple DEP release device task")
 
+	case AppleMDMRetryFleetdInstallTask:
+		err := a.runRetryFleetdInstall(ctx, args)
+		return ctxerr.Wrap(ctx, err, "running retry Apple fleetd install task")
+
 	default:
 		return ctxerr.Errorf(ctx, "unknown task: %v", args.Task)
 	}
 }
 
 func (a *AppleMDM) runPostManualEnrollment(ctx context.Context, args appleMDMArgs) error {
-	if _, err := a.installFleetd(ctx, args.HostUUID); err != nil {
+	if _, err := a.installFleetd(ctx, args.HostUUID, 0); err != nil {
 		return ctxerr.Wrap(ctx, err, "installing post-enrollment packages")
 	}
 
@@ -94,7 +103,7 @@ func (a *AppleMDM) runPostManualEnrollment(ctx context.Context, args appleMDMArg
 func (a *AppleMDM) runPostDEPEnrollment(ctx context.Context, args appleMDMArgs) error {
 	var awaitCmdUUIDs []string
 
-	fleetdCmdUUID, err := a.installFleetd(ctx, args.HostUUID)
+	fleetdCmdUUID, err := a.installFleetd(ctx, args.HostUUID, 0)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "installing post-enrollment packages")
 	}
@@ -108,6 +117,13 @@ func (a *AppleMDM) runPostDEPEnrollment(ctx context.Context, args appleMDMArgs)
 		awaitCmdUUIDs = append(awaitCmdUUIDs, bootstrapCmdUUID)
 	}
 
+	// DEP-enrolled devices are supervised, so they can escrow the code that
+	// bypasses their Activation Lock. The device is not kept waiting for it,
+	// as the code is only available once Activation Lock is turned on.
+	if err := a.Commander.ActivationLockBypassCode(ctx, []string{args.HostUUID}, uuid.New().String()); err != nil {
+		return ctxerr.Wrap(ctx, err, "sending ActivationLockBypassCode command")
+	}
+
 	if ref := args.EnrollReference; ref != "" {
 		a.Log.Log("info", "got an enroll_reference", "host_uuid", args.HostUUID, "ref", ref)
 		appCfg, err := a.Datastore.AppConfig(ctx)
@@ -136,8 +152,11 @@ func (a *AppleMDM) runPostDEPEnrollment(ctx context.Context, args appleMDMArgs)
 				ctx,
 				[]string{args.HostUUID},
 				cmdUUID,
-				acct.Fullname,
-				acct.Username,
+				apple_mdm.AccountConfigurationOptions{
+					PrimaryAccountFullName: acct.Fullname,
+					PrimaryAccountUserName: acct.Username,
+					LockPrimaryAccountInfo: true,
+				},
 			); err != nil {
 				return ctxerr.Wrap(ctx, err, "sending AccountConfiguration command")
 			}
@@ -265,12 +284,32 @@ func (a *AppleMDM) runPostDEPReleaseDevice(ctx context.Context, args appleMDMArg
 	return nil
 }
 
-func (a *AppleMDM) installFleetd(ctx context.Context, hostUUID string) (string, error) {
+func (a *AppleMDM) runRetryFleetdInstall(ctx context.Context, args appleMDMArgs) error {
+	install, err := a.Datastore.GetHostMDMFleetdInstall(ctx, args.HostUUID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host fleetd install")
+	}
+	if install.Status != fleet.MDMFleetdInstallFailed {
+		// fleetd was re-installed in the meantime (e.g. the host re-enrolled),
+		// nothing to do.
+		return nil
+	}
+
+	if _, err := a.installFleetd(ctx, args.HostUUID, install.Retries+1); err != nil {
+		return ctxerr.Wrap(ctx, err, "re-sending fleetd install command")
+	}
+	return nil
+}
+
+func (a *AppleMDM) installFleetd(ctx context.Context, hostUUID string, retries uint) (string, error) {
 	cmdUUID := uuid.New().String()
 	if err := a.Commander.InstallEnterpriseApplication(ctx, []string{hostUUID}, cmdUUID, apple_mdm.FleetdPublicManifestURL); err != nil {
 		return "", err
 	}
-	a.Log.Log("info", "sent command to install fleetd", "host_uuid", hostUUID)
+	if err := a.Datastore.RecordHostFleetdInstall(ctx, cmdUUID, hostUUID, retries); err != nil {
+		return "", err
+	}
+	a.Log.Log("info", "sent command to install fleetd", "host_uuid", hostUUID, "retries", retries)
 	return cmdUUID, nil
 }
 
@@ -349,10 +388,14 @@ func QueueAppleMDMJob(
 		EnrollmentCommands: enrollmentCommandUUIDs,
 	}
 
-	// the release device task is always added with a delay
+	// the release device and retry fleetd install tasks are always added with
+	// a delay
 	var delay time.Duration
-	if task == AppleMDMPostDEPReleaseDeviceTask {
+	switch task {
+	case AppleMDMPostDEPReleaseDeviceTask:
 		delay = 30 * time.Second
+	case AppleMDMRetryFleetdInstallTask:
+		delay = fleetdInstallRetryDelay
 	}
 	job, err := QueueJobWithDelay(ctx, ds, appleMDMJobName, args, delay)
 	if err != nil {
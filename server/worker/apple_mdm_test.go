@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -210,7 +211,7 @@ func TestAppleMDM(t *testing.T) {
 		require.Contains(t, string(*jobs[0].Args), AppleMDMPostDEPReleaseDeviceTask)
 		require.Equal(t, 0, jobs[0].Retries) // hasn't run yet
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 	})
 
 	t.Run("installs default manifest, manual release", func(t *testing.T) {
@@ -244,7 +245,7 @@ func TestAppleMDM(t *testing.T) {
 		// there is no post-DEP release device job pending
 		require.Empty(t, jobs)
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 	})
 
 	t.Run("installs custom bootstrap manifest", func(t *testing.T) {
@@ -288,7 +289,7 @@ func TestAppleMDM(t *testing.T) {
 		require.Contains(t, string(*jobs[0].Args), AppleMDMPostDEPReleaseDeviceTask)
 		require.Equal(t, 0, jobs[0].Retries) // hasn't run yet
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 
 		ms, err := ds.GetHostMDMMacOSSetup(ctx, h.ID)
 		require.NoError(t, err)
@@ -339,7 +340,7 @@ func TestAppleMDM(t *testing.T) {
 		require.Contains(t, string(*jobs[0].Args), AppleMDMPostDEPReleaseDeviceTask)
 		require.Equal(t, 0, jobs[0].Retries) // hasn't run yet
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 
 		ms, err := ds.GetHostMDMMacOSSetup(ctx, h.ID)
 		require.NoError(t, err)
@@ -388,7 +389,7 @@ func TestAppleMDM(t *testing.T) {
 		// there is no post-DEP release device job pending
 		require.Empty(t, jobs)
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 
 		ms, err := ds.GetHostMDMMacOSSetup(ctx, h.ID)
 		require.NoError(t, err)
@@ -471,7 +472,7 @@ func TestAppleMDM(t *testing.T) {
 		require.Equal(t, 0, jobs[0].Retries) // hasn't run yet
 
 		// confirm that AccountConfiguration command was not enqueued
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 	})
 
 	t.Run("enroll reference with SSO enabled", func(t *testing.T) {
@@ -525,7 +526,7 @@ func TestAppleMDM(t *testing.T) {
 		require.Contains(t, string(*jobs[0].Args), AppleMDMPostDEPReleaseDeviceTask)
 		require.Equal(t, 0, jobs[0].Retries) // hasn't run yet
 
-		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "AccountConfiguration"}, getEnqueuedCommandTypes(t))
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "AccountConfiguration", "ActivationLockBypassCode"}, getEnqueuedCommandTypes(t))
 	})
 
 	t.Run("installs fleetd for manual enrollments", func(t *testing.T) {
@@ -556,5 +557,52 @@ func TestAppleMDM(t *testing.T) {
 		require.NoError(t, err)
 		require.Empty(t, jobs)
 		require.ElementsMatch(t, []string{"InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+
+		install, err := ds.GetHostMDMFleetdInstall(ctx, h.UUID)
+		require.NoError(t, err)
+		require.Equal(t, fleet.MDMFleetdInstallPending, install.Status)
+		require.Zero(t, install.Retries)
+	})
+
+	t.Run("retries fleetd install", func(t *testing.T) {
+		defer mysql.TruncateTables(t, ds)
+
+		h := createEnrolledHost(t, 1, nil, true)
+
+		mdmWorker := &AppleMDM{
+			Datastore: ds,
+			Log:       nopLog,
+			Commander: apple_mdm.NewMDMAppleCommander(mdmStorage, mockPusher{}, config.MDMConfig{}),
+		}
+		enrollArgs, err := json.Marshal(appleMDMArgs{Task: AppleMDMPostManualEnrollmentTask, HostUUID: h.UUID})
+		require.NoError(t, err)
+		retryArgs, err := json.Marshal(appleMDMArgs{Task: AppleMDMRetryFleetdInstallTask, HostUUID: h.UUID})
+		require.NoError(t, err)
+
+		err = mdmWorker.Run(ctx, enrollArgs)
+		require.NoError(t, err)
+		install, err := ds.GetHostMDMFleetdInstall(ctx, h.UUID)
+		require.NoError(t, err)
+
+		// the install is still pending, nothing to retry
+		err = mdmWorker.Run(ctx, retryArgs)
+		require.NoError(t, err)
+		require.Len(t, getEnqueuedCommandTypes(t), 1)
+
+		// the install failed, it gets retried
+		mysql.ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+			_, err := q.ExecContext(ctx, `INSERT INTO nano_command_results (id, command_uuid, status, result) VALUES (?, ?, 'Error', '<?xml')`,
+				h.UUID, install.CommandUUID)
+			return err
+		})
+		err = mdmWorker.Run(ctx, retryArgs)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"InstallEnterpriseApplication", "InstallEnterpriseApplication"}, getEnqueuedCommandTypes(t))
+
+		retried, err := ds.GetHostMDMFleetdInstall(ctx, h.UUID)
+		require.NoError(t, err)
+		require.Equal(t, fleet.MDMFleetdInstallPending, retried.Status)
+		require.NotEqual(t, install.CommandUUID, retried.CommandUUID)
+		require.EqualValues(t, 1, retried.Retries)
 	})
 }
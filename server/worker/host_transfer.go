@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Name of the host transfer job as registered in the worker.
+const hostTransferJobName = "host_transfer"
+
+// HostTransfer is the job processor for the host_transfer job. It reconciles
+// the platform-specific state of hosts that were transferred to another team
+// so that it doesn't have to wait for the next run of the corresponding cron
+// jobs:
+//
+//   - the MDM profiles delta (old team's profiles to remove, new team's
+//     profiles to install, including the disk encryption profile), computed
+//     when the hosts were transferred, is delivered by triggering the MDM
+//     profile manager;
+//   - the hosts are flagged for a refetch so that the policies of their new
+//     team are evaluated on their next check-in.
+type HostTransfer struct {
+	Datastore fleet.Datastore
+	Log       kitlog.Logger
+	// CronSchedules is used to trigger the MDM profile manager, it may be nil
+	// in which case the profiles are delivered on its next scheduled run.
+	CronSchedules fleet.CronSchedulesService
+}
+
+// Name returns the name of the job.
+func (h *HostTransfer) Name() string {
+	return hostTransferJobName
+}
+
+// hostTransferArgs is the payload for the host transfer job.
+type hostTransferArgs struct {
+	TeamID  *uint  `json:"team_id,omitempty"`
+	HostIDs []uint `json:"host_ids"`
+}
+
+// Run executes the host_transfer job.
+func (h *HostTransfer) Run(ctx context.Context, argsJSON json.RawMessage) error {
+	var args hostTransferArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal args")
+	}
+
+	hosts, err := h.Datastore.ListHostsLiteByIDs(ctx, args.HostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list transferred hosts")
+	}
+
+	// ignore the hosts that were deleted or transferred again since the job was
+	// queued, the later transfer queued its own job.
+	hostIDs := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		if sameTeam(host.TeamID, args.TeamID) {
+			hostIDs = append(hostIDs, host.ID)
+		}
+	}
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	for _, hostID := range hostIDs {
+		if err := h.Datastore.UpdateHostRefetchRequested(ctx, hostID, true); err != nil {
+			return ctxerr.Wrap(ctx, err, "request refetch of transferred host")
+		}
+	}
+
+	if h.CronSchedules != nil {
+		// the trigger fails if the profile manager is already running, in which
+		// case the profiles will be delivered on its next run.
+		if err := h.CronSchedules.TriggerCronSchedule(string(fleet.CronMDMAppleProfileManager)); err != nil {
+			level.Debug(h.Log).Log("msg", "trigger mdm profile manager", "err", err)
+		}
+	}
+
+	level.Debug(h.Log).Log("msg", "reconciled transferred hosts", "hosts_count", len(hostIDs))
+	return nil
+}
+
+func sameTeam(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// QueueHostTransferJob queues a host_transfer job to reconcile the state of
+// the hosts transferred to the team (nil for "no team"), to be processed
+// asynchronously via the worker.
+func QueueHostTransferJob(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	teamID *uint,
+	hostIDs []uint,
+) error {
+	attrs := []interface{}{
+		"enabled", "true",
+		hostTransferJobName, "queued",
+		"hosts_count", len(hostIDs),
+	}
+	if teamID != nil {
+		attrs = append(attrs, "team_id", *teamID)
+	}
+	level.Info(logger).Log(attrs...)
+
+	args := &hostTransferArgs{
+		TeamID:  teamID,
+		HostIDs: hostIDs,
+	}
+	job, err := QueueJob(ctx, ds, hostTransferJobName, args)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "queueing job")
+	}
+	level.Debug(logger).Log("job_id", job.ID)
+	return nil
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/datastore/mysql"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCronSchedules struct {
+	triggered []string
+}
+
+func (m *mockCronSchedules) TriggerCronSchedule(name string) error {
+	m.triggered = append(m.triggered, name)
+	return nil
+}
+
+func (m *mockCronSchedules) ScheduleIntervals() map[string]time.Duration {
+	return nil
+}
+
+func TestHostTransfer(t *testing.T) {
+	ctx := context.Background()
+	ds := mysql.CreateMySQLDS(t)
+	// call TruncateTables immediately as some DB migrations may create jobs
+	mysql.TruncateTables(t, ds)
+
+	hosts := make([]*fleet.Host, 3)
+	for i := 0; i < len(hosts); i++ {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			Hostname:      fmt.Sprintf("test-host%d-name", i),
+			OsqueryHostID: ptr.String(fmt.Sprintf("osquery-%d", i)),
+			NodeKey:       ptr.String(fmt.Sprintf("nodekey-%d", i)),
+			UUID:          fmt.Sprintf("test-uuid-%d", i),
+			Platform:      "darwin",
+		})
+		require.NoError(t, err)
+		hosts[i] = h
+	}
+
+	tm1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	tm2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	logger := kitlog.NewNopLogger()
+	crons := &mockCronSchedules{}
+	transferJob := &HostTransfer{
+		Datastore:     ds,
+		Log:           logger,
+		CronSchedules: crons,
+	}
+	w := NewWorker(ds, logger)
+	w.Register(transferJob)
+
+	checkRefetch := func(want ...bool) {
+		for i, h := range hosts {
+			got, err := ds.HostLite(ctx, h.ID)
+			require.NoError(t, err)
+			require.Equal(t, want[i], got.RefetchRequested, "host %d", i)
+		}
+	}
+
+	// hosts[0, 1] are transferred to team1
+	err = ds.AddHostsToTeam(ctx, &tm1.ID, []uint{hosts[0].ID, hosts[1].ID})
+	require.NoError(t, err)
+	err = QueueHostTransferJob(ctx, ds, logger, &tm1.ID, []uint{hosts[0].ID, hosts[1].ID})
+	require.NoError(t, err)
+
+	// hosts[1] is transferred again to team2 before the job runs
+	err = ds.AddHostsToTeam(ctx, &tm2.ID, []uint{hosts[1].ID})
+	require.NoError(t, err)
+
+	// run the worker, only hosts[0] is reconciled by the first job
+	err = w.ProcessJobs(ctx)
+	require.NoError(t, err)
+	checkRefetch(true, false, false)
+	require.Equal(t, []string{string(fleet.CronMDMAppleProfileManager)}, crons.triggered)
+
+	// the job for the second transfer reconciles hosts[1]
+	err = QueueHostTransferJob(ctx, ds, logger, &tm2.ID, []uint{hosts[1].ID})
+	require.NoError(t, err)
+	err = w.ProcessJobs(ctx)
+	require.NoError(t, err)
+	checkRefetch(true, true, false)
+	require.Len(t, crons.triggered, 2)
+
+	// transfer hosts[2] to no team, but delete it before the job runs
+	err = QueueHostTransferJob(ctx, ds, logger, nil, []uint{hosts[2].ID})
+	require.NoError(t, err)
+	err = ds.DeleteHost(ctx, hosts[2].ID)
+	require.NoError(t, err)
+	err = w.ProcessJobs(ctx)
+	require.NoError(t, err)
+	// nothing to reconcile, the profile manager is not triggered
+	require.Len(t, crons.triggered, 2)
+
+	// no more jobs pending
+	jobs, err := ds.GetQueuedJobs(ctx, 10, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+}
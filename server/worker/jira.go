@@ -1,7 +1,6 @@
 package worker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -332,17 +331,10 @@ func (j *Jira) runFailingPolicy(ctx context.Context, cli JiraClient, args jiraAr
 }
 
 func (j *Jira) createTemplatedIssue(ctx context.Context, cli JiraClient, summaryTpl, descTpl *template.Template, args interface{}) (*jira.Issue, error) {
-	var buf bytes.Buffer
-	if err := summaryTpl.Execute(&buf, args); err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "execute summary template")
-	}
-	summary := buf.String()
-
-	buf.Reset() // reuse buffer
-	if err := descTpl.Execute(&buf, args); err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "execute description template")
+	summary, description, err := executeTicketTemplates(summaryTpl, descTpl, args)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
 	}
-	description := buf.String()
 
 	issue := &jira.Issue{
 		Fields: &jira.IssueFields{
@@ -425,13 +417,7 @@ func QueueJiraFailingPolicyJob(ctx context.Context, ds fleet.Datastore, logger k
 
 	level.Info(logger).Log(attrs...)
 
-	args := &failingPolicyArgs{
-		PolicyID:       policy.ID,
-		PolicyName:     policy.Name,
-		PolicyCritical: policy.Critical,
-		Hosts:          hosts,
-		TeamID:         policy.TeamID,
-	}
+	args := newFailingPolicyArgs(policy, hosts)
 	job, err := QueueJob(ctx, ds, jiraName, jiraArgs{FailingPolicy: args})
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "queueing job")
@@ -439,3 +425,11 @@ func QueueJiraFailingPolicyJob(ctx context.Context, ds fleet.Datastore, logger k
 	level.Debug(logger).Log("job_id", job.ID)
 	return nil
 }
+
+// RenderJiraFailingPolicyIssue returns the summary and description of the
+// Jira issue that would be created for the hosts failing the policy, without
+// creating it.
+func RenderJiraFailingPolicyIssue(fleetURL string, policy *fleet.Policy, hosts []fleet.PolicySetHost) (summary, description string, err error) {
+	tplArgs := newFailingPoliciesTplArgs(fleetURL, newFailingPolicyArgs(policy, hosts))
+	return executeTicketTemplates(jiraTemplates.FailingPolicySummary, jiraTemplates.FailingPolicyDescription, tplArgs)
+}
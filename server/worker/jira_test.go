@@ -454,3 +454,15 @@ func TestJiraRunClientUpdate(t *testing.T) {
 	require.Len(t, clients[2].issues, 1)
 	require.NotContains(t, clients[2].issues[0].Fields.Description, "Critical")
 }
+
+func TestRenderJiraFailingPolicyIssue(t *testing.T) {
+	policy := &fleet.Policy{PolicyData: fleet.PolicyData{ID: 1, Name: "test-policy", TeamID: ptr.Uint(2)}}
+	hosts := []fleet.PolicySetHost{{ID: 1, Hostname: "test-1", DisplayName: "test-1"}, {ID: 2, Hostname: "test-2", DisplayName: "test-2"}}
+
+	summary, description, err := RenderJiraFailingPolicyIssue("https://fleet.example.com", policy, hosts)
+	require.NoError(t, err)
+	require.Contains(t, summary, "test-policy")
+	require.Contains(t, description, "https://fleet.example.com/hosts/1")
+	require.Contains(t, description, "https://fleet.example.com/hosts/2")
+	require.Contains(t, description, "test-2")
+}
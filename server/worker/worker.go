@@ -1,9 +1,11 @@
 package worker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"text/template"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
@@ -222,6 +224,32 @@ type failingPoliciesTplArgs struct {
 	Hosts          []fleet.PolicySetHost
 }
 
+func newFailingPolicyArgs(policy *fleet.Policy, hosts []fleet.PolicySetHost) *failingPolicyArgs {
+	return &failingPolicyArgs{
+		PolicyID:       policy.ID,
+		PolicyName:     policy.Name,
+		PolicyCritical: policy.Critical,
+		Hosts:          hosts,
+		TeamID:         policy.TeamID,
+	}
+}
+
+// executeTicketTemplates returns the summary and description of a Jira issue
+// or Zendesk ticket rendered with the args.
+func executeTicketTemplates(summaryTpl, descTpl *template.Template, args interface{}) (summary, description string, err error) {
+	var buf bytes.Buffer
+	if err := summaryTpl.Execute(&buf, args); err != nil {
+		return "", "", fmt.Errorf("execute summary template: %w", err)
+	}
+	summary = buf.String()
+
+	buf.Reset() // reuse buffer
+	if err := descTpl.Execute(&buf, args); err != nil {
+		return "", "", fmt.Errorf("execute description template: %w", err)
+	}
+	return summary, buf.String(), nil
+}
+
 func newFailingPoliciesTplArgs(fleetURL string, args *failingPolicyArgs) *failingPoliciesTplArgs {
 	return &failingPoliciesTplArgs{
 		FleetURL:       fleetURL,
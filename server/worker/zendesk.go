@@ -1,7 +1,6 @@
 package worker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -333,17 +332,10 @@ func (z *Zendesk) runFailingPolicy(ctx context.Context, cli ZendeskClient, args
 }
 
 func (z *Zendesk) createTemplatedTicket(ctx context.Context, cli ZendeskClient, summaryTpl, descTpl *template.Template, args interface{}) (*zendesk.Ticket, error) {
-	var buf bytes.Buffer
-	if err := summaryTpl.Execute(&buf, args); err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "execute summary template")
-	}
-	summary := buf.String()
-
-	buf.Reset() // reuse buffer
-	if err := descTpl.Execute(&buf, args); err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "execute description template")
+	summary, description, err := executeTicketTemplates(summaryTpl, descTpl, args)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
 	}
-	description := buf.String()
 
 	ticket := &zendesk.Ticket{
 		Subject: summary,
@@ -421,13 +413,7 @@ func QueueZendeskFailingPolicyJob(ctx context.Context, ds fleet.Datastore, logge
 
 	level.Info(logger).Log(attrs...)
 
-	args := &failingPolicyArgs{
-		PolicyID:       policy.ID,
-		PolicyName:     policy.Name,
-		PolicyCritical: policy.Critical,
-		TeamID:         policy.TeamID,
-		Hosts:          hosts,
-	}
+	args := newFailingPolicyArgs(policy, hosts)
 	job, err := QueueJob(ctx, ds, zendeskName, zendeskArgs{FailingPolicy: args})
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "queueing job")
@@ -435,3 +421,11 @@ func QueueZendeskFailingPolicyJob(ctx context.Context, ds fleet.Datastore, logge
 	level.Debug(logger).Log("job_id", job.ID)
 	return nil
 }
+
+// RenderZendeskFailingPolicyTicket returns the subject and body of the
+// Zendesk ticket that would be created for the hosts failing the policy,
+// without creating it.
+func RenderZendeskFailingPolicyTicket(fleetURL string, policy *fleet.Policy, hosts []fleet.PolicySetHost) (subject, body string, err error) {
+	tplArgs := newFailingPoliciesTplArgs(fleetURL, newFailingPolicyArgs(policy, hosts))
+	return executeTicketTemplates(zendeskTemplates.FailingPolicySummary, zendeskTemplates.FailingPolicyDescription, tplArgs)
+}
@@ -435,3 +435,15 @@ func TestZendeskRunClientUpdate(t *testing.T) {
 	require.Len(t, clients[2].tickets, 1)
 	require.NotContains(t, clients[2].tickets[0].Comment.Body, "Critical")
 }
+
+func TestRenderZendeskFailingPolicyTicket(t *testing.T) {
+	policy := &fleet.Policy{PolicyData: fleet.PolicyData{ID: 1, Name: "test-policy", TeamID: ptr.Uint(2)}}
+	hosts := []fleet.PolicySetHost{{ID: 1, Hostname: "test-1", DisplayName: "test-1"}, {ID: 2, Hostname: "test-2", DisplayName: "test-2"}}
+
+	subject, body, err := RenderZendeskFailingPolicyTicket("https://fleet.example.com", policy, hosts)
+	require.NoError(t, err)
+	require.Contains(t, subject, "test-policy")
+	require.Contains(t, body, "https://fleet.example.com/hosts/1")
+	require.Contains(t, body, "https://fleet.example.com/hosts/2")
+	require.Contains(t, body, "test-2")
+}